@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bgdnvk/clanker/internal/dbcontext"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var agentsCmd = &cobra.Command{
+	Use:   "agents",
+	Short: "Run a specific agent directly, skipping natural-language routing",
+	Long: `Each subcommand hands the question straight to one agent, the same agent
+clanker ask would route it to, so scripts get predictable behaviour. Plans are
+printed, stored and hooked exactly as with clanker ask.
+
+Run without a subcommand to list the agents.
+
+Examples:
+  clanker agents k8s "scale deployment web in shop to 5 replicas"
+  clanker agents cf dns "add a CNAME www pointing to example.pages.dev"
+  clanker agents iam --role-arn arn:aws:iam::123456789012:role/app "is this role over-privileged?"
+  clanker agents aws-cost "top 5 services by cost this week"
+  clanker agents aws-audit "audit my AWS security"
+  clanker agents aws-logs "show errors in lambda checkout from the last hour"
+  clanker agents aws-ssm "run 'uptime' on all instances tagged role=web"
+  clanker agents aws-rds "take a snapshot of orders-db"
+  clanker agents aws-lambda "deploy ./dist/checkout.zip to lambda checkout"
+  clanker agents azure-infra "deallocate azure vm batch-01"
+  clanker agents secrets "which deployments use secret db-credentials"
+  clanker agents certs "which certs expire in the next 14 days"
+  clanker agents dns-truth "where does api.example.com actually resolve"
+  clanker agents github-actions "re-run the failed jobs of run 9876543210 in acme/shop"
+  clanker agents pr-review "summarize PR #123 in acme/shop and flag risky changes"
+  clanker agents gitlab-ci "retry the failed gitlab pipeline on main"
+  clanker agents terraform-state "is there drift in workspace prod"
+  clanker agents aws --maker "create a t3.small instance in the default VPC"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printAgents(os.Stdout, cmd)
+		return nil
+	},
+}
+
+var agentsCfCmd = newAgentCmd("cf", "Cloudflare agent (picks the sub-agent from the question)", func(cmd *cobra.Command, question string, debug bool) error {
+	return handleCloudflareQuery(cmd.Context(), question, debug)
+})
+
+// cloudflareSubAgents are the Cloudflare sub-agents that can be run directly
+var cloudflareSubAgents = []struct {
+	name, short string
+}{
+	{"dns", "Cloudflare DNS agent: zones and records"},
+	{"waf", "Cloudflare WAF agent: firewall rules, rate limits and security level"},
+	{"workers", "Cloudflare Workers agent: Workers, KV, D1, R2 and Pages"},
+	{"analytics", "Cloudflare analytics agent: traffic, bandwidth and requests"},
+	{"zerotrust", "Cloudflare Zero Trust agent: tunnels and Access apps and policies"},
+}
+
+func init() {
+	rootCmd.AddCommand(agentsCmd)
+
+	k8sAgent := newAgentCmd("k8s", "Kubernetes agent: cluster queries, workload changes, provisioning and deploys", func(cmd *cobra.Command, question string, debug bool) error {
+		kubeconfig, _ := cmd.Flags().GetString("kubeconfig")
+		if kubeconfig == "" {
+			kubeconfig = viper.GetString("kubernetes.kubeconfig")
+		}
+		return handleK8sQuery(cmd.Context(), question, debug, kubeconfig)
+	})
+	k8sAgent.Flags().String("kubeconfig", "", "Path to kubeconfig (default: kubernetes.kubeconfig or ~/.kube/config)")
+
+	for _, sub := range cloudflareSubAgents {
+		name := sub.name
+		agentsCfCmd.AddCommand(newAgentCmd(name, sub.short, func(cmd *cobra.Command, question string, debug bool) error {
+			client, err := newCloudflareAgentClient(cmd.Context(), debug)
+			if err != nil {
+				return err
+			}
+			return runCloudflareSubAgent(cmd.Context(), client, name, question, debug)
+		}))
+	}
+
+	iamAgent := newAgentCmd("iam", "AWS IAM agent: roles, policies, permission analysis and planned changes", func(cmd *cobra.Command, question string, debug bool) error {
+		roleARN, _ := cmd.Flags().GetString("role-arn")
+		policyARN, _ := cmd.Flags().GetString("policy-arn")
+		return handleIAMQuery(cmd.Context(), question, debug, roleARN, policyARN)
+	})
+	iamAgent.Flags().String("role-arn", "", "Scope the question to a specific role ARN")
+	iamAgent.Flags().String("policy-arn", "", "Scope the question to a specific policy ARN")
+
+	awsCostAgent := newAgentCmd("aws-cost", "AWS cost agent: spend by service, region or account from Cost Explorer", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		return handleAWSCostQuery(cmd.Context(), question, debug, profile)
+	})
+	awsCostAgent.Flags().String("profile", "", "AWS profile to use")
+	_ = awsCostAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	awsAuditAgent := newAgentCmd("aws-audit", "AWS security audit agent: public buckets, open ports, encryption and MFA, with a remediation plan", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		return handleAWSAuditQuery(cmd.Context(), question, debug, profile)
+	})
+	awsAuditAgent.Flags().String("profile", "", "AWS profile to use")
+	_ = awsAuditAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	awsLogsAgent := newAgentCmd("aws-logs", "AWS logs agent: CloudWatch Logs Insights queries for a log group or Lambda function", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		logGroups, _ := cmd.Flags().GetStringSlice("log-group")
+		since, _ := cmd.Flags().GetDuration("since")
+		query, _ := cmd.Flags().GetString("query")
+		return handleAWSLogsQuery(cmd.Context(), question, debug, profile, awsLogsOptions{LogGroups: logGroups, Since: since, Query: query})
+	})
+	awsLogsAgent.Flags().String("profile", "", "AWS profile to use")
+	awsLogsAgent.Flags().StringSlice("log-group", nil, "Log group to query instead of the ones named in the question (repeatable)")
+	awsLogsAgent.Flags().Duration("since", 0, "Query this far back instead of the period in the question (e.g. 30m, 6h)")
+	awsLogsAgent.Flags().String("query", "", "Logs Insights query to run instead of the generated one")
+	_ = awsLogsAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	awsSSMAgent := newAgentCmd("aws-ssm", "AWS SSM agent: Session Manager shells and commands on instances by ID, tag or kubeadm cluster", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		return handleAWSSSMQuery(cmd.Context(), question, debug, profile)
+	})
+	awsSSMAgent.Flags().String("profile", "", "AWS profile to use")
+	_ = awsSSMAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	awsRDSAgent := newAgentCmd("aws-rds", "AWS RDS agent: instances and clusters with metrics, maintenance, and snapshot, restore and resize plans", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		return handleAWSRDSQuery(cmd.Context(), question, debug, profile)
+	})
+	awsRDSAgent.Flags().String("profile", "", "AWS profile to use")
+	_ = awsRDSAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	awsLambdaAgent := newAgentCmd("aws-lambda", "AWS Lambda agent: functions with error rates, recent errors, and environment and deploy plans", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		return handleAWSLambdaQuery(cmd.Context(), question, debug, profile)
+	})
+	awsLambdaAgent.Flags().String("profile", "", "AWS profile to use")
+	_ = awsLambdaAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	azureInfraAgent := newAgentCmd("azure-infra", "Azure infrastructure agent: VMs, storage accounts, VNets, Function Apps and registries, with create, power and resize plans", func(cmd *cobra.Command, question string, debug bool) error {
+		subscription, _ := cmd.Flags().GetString("azure-subscription")
+		return handleAzureInfraQuery(cmd.Context(), question, debug, subscription)
+	})
+	azureInfraAgent.Flags().String("azure-subscription", "", "Azure subscription ID to use")
+
+	secretsAgent := newAgentCmd("secrets", "Secrets agent: Secrets Manager, Parameter Store, GCP and Kubernetes secrets by metadata, references, and rotate and create plans", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		return handleSecretsQuery(cmd.Context(), question, debug, profile)
+	})
+	secretsAgent.Flags().String("profile", "", "AWS profile to use")
+	_ = secretsAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	certsAgent := newAgentCmd("certs", "Certificate agent: ACM, Cloudflare, GCP and cert-manager certificates by days left, with renewal plans", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		return handleCertsQuery(cmd.Context(), question, debug, profile)
+	})
+	certsAgent.Flags().String("profile", "", "AWS profile to use")
+	_ = certsAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	dnsTruthAgent := newAgentCmd("dns-truth", "DNS truth agent: where a name resolves, whether Route53 or Cloudflare serves it, and conflicting or dangling records", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		return handleDNSTruthQuery(cmd.Context(), question, debug, profile)
+	})
+	dnsTruthAgent.Flags().String("profile", "", "AWS profile to use")
+	_ = dnsTruthAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	diagramAgent := newAgentCmd("diagram", "Diagram agent: cluster and RDS inventory as JSON, Mermaid or draw.io, kept up to date by later questions", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		return handleDiagramQuery(cmd.Context(), question, debug, profile)
+	})
+	diagramAgent.Flags().String("profile", "", "AWS profile to use for RDS inventory")
+	_ = diagramAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	awsAgent := newAskProviderAgentCmd("aws", "AWS agent: infrastructure questions, or plans with --maker", "profile", "AWS profile to use")
+	_ = awsAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+	gcpAgent := newAskProviderAgentCmd("gcp", "GCP agent: infrastructure questions, or plans with --maker", "gcp-project", "GCP project ID to use")
+	azureAgent := newAskProviderAgentCmd("azure", "Azure agent: infrastructure questions, or plans with --maker", "azure-subscription", "Azure subscription ID to use")
+
+	databaseAgent := newAgentCmd("database", "Database agent: schema, size and read-only queries on configured connections", func(cmd *cobra.Command, question string, debug bool) error {
+		connection, _ := cmd.Flags().GetString("connection")
+		return handleDatabaseQuery(cmd.Context(), question, debug, connection)
+	})
+	databaseAgent.Flags().String("connection", "", "Database connection name (default: the configured default)")
+	_ = databaseAgent.RegisterFlagCompletionFunc("connection", completeDatabaseConnections)
+
+	observabilityAgent := newAgentCmd("observability", "Observability agent: logs, traces, metrics, alerts and errors", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		return handleObservabilityQuery(cmd.Context(), question, debug, profile)
+	})
+	observabilityAgent.Flags().String("profile", "", "AWS profile to use for CloudWatch context")
+	_ = observabilityAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	terraformStateAgent := newAgentCmd("terraform-state", "Terraform state agent: state list and show, drift, and AWS resources managed outside Terraform", func(cmd *cobra.Command, question string, debug bool) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		return handleTerraformStateQuery(cmd.Context(), question, debug, profile)
+	})
+	terraformStateAgent.Flags().String("profile", "", "AWS profile for the live resource comparison")
+	_ = terraformStateAgent.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	agentsCmd.AddCommand(
+		k8sAgent,
+		agentsCfCmd,
+		iamAgent,
+		awsCostAgent,
+		awsAuditAgent,
+		awsLogsAgent,
+		awsSSMAgent,
+		awsRDSAgent,
+		awsLambdaAgent,
+		azureInfraAgent,
+		secretsAgent,
+		certsAgent,
+		dnsTruthAgent,
+		diagramAgent,
+		awsAgent,
+		gcpAgent,
+		azureAgent,
+		databaseAgent,
+		observabilityAgent,
+		newAgentCmd("cicd", "CI/CD agent: GitHub Actions workflows and runs", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleCICDQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("github-actions", "GitHub Actions agent: workflow_dispatch, re-run, cancel and deployment approval plans", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleGitHubActionsQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("pr-review", "PR review agent: pull request summary with risk areas, missing tests and infra changes", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleGitHubPRReviewQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("gitlab-ci", "GitLab CI agent: pipeline and job retry plans", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleGitLabCIQuery(cmd.Context(), question, debug)
+		}),
+		terraformStateAgent,
+		newAgentCmd("software-blocks", "Software blocks agent: architecture building blocks for an app", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleSoftwareBlocksQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("data-flow", "Data flow agent: how data moves between services", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleDataFlowQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("hermes", "Hermes agent", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleHermesQuery(cmd.Context(), question, "", debug)
+		}),
+		newAgentCmd("claude-code", "Claude Code agent", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleClaudeCodeQuery(cmd.Context(), question, "", debug)
+		}),
+		newAgentCmd("digitalocean", "Digital Ocean agent", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleDigitalOceanQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("hetzner", "Hetzner Cloud agent", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleHetznerQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("oracle", "Oracle Cloud agent", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleOracleQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("vercel", "Vercel agent", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleVercelQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("flyio", "Fly.io agent", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleFlyioQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("railway", "Railway agent", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleRailwayQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("verda", "Verda Cloud agent", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleVerdaQuery(cmd.Context(), question, debug)
+		}),
+		newAgentCmd("tencent", "Tencent Cloud agent", func(cmd *cobra.Command, question string, debug bool) error {
+			return handleTencentQuery(cmd.Context(), question, debug)
+		}),
+	)
+
+	_ = askCmd.RegisterFlagCompletionFunc("agent", cobra.FixedCompletions(
+		[]string{"hermes", "claude-code", "database", "cicd", "observability", "software-blocks", "data_flow", "copilot", "codex", "claude"},
+		cobra.ShellCompDirectiveNoFileComp,
+	))
+	_ = askCmd.RegisterFlagCompletionFunc("db-connection", completeDatabaseConnections)
+	_ = askCmd.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+}
+
+// newAgentCmd builds an agents subcommand that passes its arguments, joined
+// into one question, to run
+func newAgentCmd(name, short string, run func(cmd *cobra.Command, question string, debug bool) error) *cobra.Command {
+	return &cobra.Command{
+		Use:               name + " <question>",
+		Short:             short,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: cobra.NoFileCompletions,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			question := strings.TrimSpace(strings.Join(args, " "))
+			if question == "" {
+				return fmt.Errorf("question cannot be empty")
+			}
+			if cmd.Context() == nil {
+				cmd.SetContext(context.Background())
+			}
+			return run(cmd, question, viper.GetBool("debug"))
+		},
+	}
+}
+
+// newAskProviderAgentCmd builds an agents subcommand for a cloud whose agent
+// lives in clanker ask, forwarding the account flag and --maker/--destroyer
+func newAskProviderAgentCmd(provider, short, accountFlag, accountUsage string) *cobra.Command {
+	c := newAgentCmd(provider, short, func(cmd *cobra.Command, question string, debug bool) error {
+		settings := map[string]string{provider: "true"}
+		for _, name := range []string{accountFlag, "maker", "destroyer"} {
+			if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+				settings[name] = f.Value.String()
+			}
+		}
+		return runAskWith(settings, question)
+	})
+	c.Flags().String(accountFlag, "", accountUsage)
+	c.Flags().Bool("maker", false, "Generate a plan (JSON) instead of answering")
+	c.Flags().Bool("destroyer", false, "Allow destructive operations in the generated plan")
+	return c
+}
+
+// runAskWith runs clanker ask with the given flags set, so commands that
+// reuse its agents share its plan output, storage and hooks
+func runAskWith(settings map[string]string, args ...string) error {
+	for name, value := range settings {
+		if err := askCmd.Flags().Set(name, value); err != nil {
+			return err
+		}
+	}
+	return askCmd.RunE(askCmd, args)
+}
+
+func printAgents(w io.Writer, cmd *cobra.Command) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGENT\tDESCRIPTION")
+	for _, sub := range cmd.Commands() {
+		if !sub.IsAvailableCommand() {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", sub.Name(), sub.Short)
+		for _, nested := range sub.Commands() {
+			fmt.Fprintf(tw, "%s %s\t%s\n", sub.Name(), nested.Name(), nested.Short)
+		}
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "\nRun: clanker agents <agent> \"<question>\"")
+}
+
+// completeDatabaseConnections completes configured database connection names
+func completeDatabaseConnections(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	connections, _, err := dbcontext.ListConnections()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := make([]string, 0, len(connections))
+	for _, c := range connections {
+		names = append(names, c.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeAWSProfiles completes the AWS profiles named in the clanker config
+func completeAWSProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	seen := make(map[string]bool)
+	if p := viper.GetString("aws.default_profile"); p != "" {
+		seen[p] = true
+	}
+	for env := range viper.GetStringMap("infra.aws.environments") {
+		if p := viper.GetString(fmt.Sprintf("infra.aws.environments.%s.profile", env)); p != "" {
+			seen[p] = true
+		}
+	}
+	profiles := make([]string, 0, len(seen))
+	for p := range seen {
+		profiles = append(profiles, p)
+	}
+	sort.Strings(profiles)
+	return profiles, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestAgentsCommandsRegistered(t *testing.T) {
+	for _, path := range [][]string{
+		{"agents", "k8s"},
+		{"agents", "cf"},
+		{"agents", "cf", "dns"},
+		{"agents", "cf", "zerotrust"},
+		{"agents", "iam"},
+		{"agents", "aws-cost"},
+		{"agents", "aws-audit"},
+		{"agents", "aws-logs"},
+		{"agents", "aws-ssm"},
+		{"agents", "aws-rds"},
+		{"agents", "aws-lambda"},
+		{"agents", "azure-infra"},
+		{"agents", "secrets"},
+		{"agents", "certs"},
+		{"agents", "dns-truth"},
+		{"agents", "diagram"},
+		{"agents", "github-actions"},
+		{"agents", "pr-review"},
+		{"agents", "gitlab-ci"},
+		{"agents", "terraform-state"},
+		{"github", "dispatch"},
+		{"github", "rerun"},
+		{"github", "cancel"},
+		{"github", "approve"},
+		{"aws", "ssm", "connect"},
+		{"aws", "ssm", "run"},
+		{"agents", "aws"},
+		{"agents", "database"},
+		{"agents", "tencent"},
+	} {
+		cmd, rest, err := rootCmd.Find(path)
+		if err != nil || len(rest) != 0 || cmd.Name() != path[len(path)-1] {
+			t.Errorf("Find(%v) = %v, %v, %v", path, cmd.Name(), rest, err)
+		}
+	}
+	if agentsIAM, _, _ := rootCmd.Find([]string{"agents", "iam"}); agentsIAM.Flags().Lookup("role-arn") == nil {
+		t.Error("agents iam is missing --role-arn")
+	}
+	if agentsAWS, _, _ := rootCmd.Find([]string{"agents", "aws"}); agentsAWS.Flags().Lookup("maker") == nil {
+		t.Error("agents aws is missing --maker")
+	}
+}
+
+func TestNewAgentCmdJoinsQuestion(t *testing.T) {
+	var got string
+	c := newAgentCmd("test", "", func(cmd *cobra.Command, question string, debug bool) error {
+		got = question
+		return nil
+	})
+	c.SetArgs([]string{"list", "all", "zones"})
+	if err := c.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if got != "list all zones" {
+		t.Errorf("question = %q", got)
+	}
+
+	c.SetArgs([]string{"  "})
+	if err := c.Execute(); err == nil {
+		t.Error("empty question should fail")
+	}
+}
+
+func TestCloudflareSubAgentFor(t *testing.T) {
+	cases := map[string]string{
+		"show firewall rules for example.com": "waf",
+		"list my workers":                     "workers",
+		"traffic for the last day":            "analytics",
+		"show firewall events from CN":        "analytics",
+		"list dns records":                    "dns",
+		"what is my account id":               "",
+	}
+	for question, want := range cases {
+		if got := cloudflareSubAgentFor(question); got != want {
+			t.Errorf("cloudflareSubAgentFor(%q) = %q, want %q", question, got, want)
+		}
+	}
+}
+
+func TestCompleteAWSProfiles(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("aws.default_profile", "prod")
+	viper.Set("infra.aws.environments", map[string]any{
+		"dev":     map[string]any{"profile": "dev-admin"},
+		"staging": map[string]any{"profile": "prod"},
+	})
+
+	got, directive := completeAWSProfiles(nil, nil, "")
+	if strings.Join(got, ",") != "dev-admin,prod" {
+		t.Errorf("profiles = %v", got)
+	}
+	if directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("directive = %v", directive)
+	}
+}
+
+func TestPrintAgents(t *testing.T) {
+	var buf bytes.Buffer
+	printAgents(&buf, agentsCmd)
+	out := buf.String()
+	for _, want := range []string{"AGENT", "k8s", "cf dns", "iam", "database"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
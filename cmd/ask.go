@@ -5,15 +5,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bgdnvk/clanker/internal/ai"
+	"github.com/bgdnvk/clanker/internal/audit"
 	"github.com/bgdnvk/clanker/internal/aws"
+	awscost "github.com/bgdnvk/clanker/internal/aws/cost"
+	awslambda "github.com/bgdnvk/clanker/internal/aws/lambda"
+	awslogs "github.com/bgdnvk/clanker/internal/aws/logs"
+	"github.com/bgdnvk/clanker/internal/aws/posture"
+	awsrds "github.com/bgdnvk/clanker/internal/aws/rds"
 	"github.com/bgdnvk/clanker/internal/azure"
+	azureinfra "github.com/bgdnvk/clanker/internal/azure/infra"
 	"github.com/bgdnvk/clanker/internal/backend"
+	"github.com/bgdnvk/clanker/internal/certs"
 	"github.com/bgdnvk/clanker/internal/claudecode"
 	"github.com/bgdnvk/clanker/internal/cloudflare"
 	cfanalytics "github.com/bgdnvk/clanker/internal/cloudflare/analytics"
@@ -23,6 +34,8 @@ import (
 	cfzerotrust "github.com/bgdnvk/clanker/internal/cloudflare/zerotrust"
 	"github.com/bgdnvk/clanker/internal/dbcontext"
 	"github.com/bgdnvk/clanker/internal/digitalocean"
+	"github.com/bgdnvk/clanker/internal/dnstruth"
+	"github.com/bgdnvk/clanker/internal/dryrun"
 	"github.com/bgdnvk/clanker/internal/flyio"
 	"github.com/bgdnvk/clanker/internal/gcp"
 	ghclient "github.com/bgdnvk/clanker/internal/github"
@@ -30,14 +43,25 @@ import (
 	"github.com/bgdnvk/clanker/internal/hetzner"
 	iamclient "github.com/bgdnvk/clanker/internal/iam"
 	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/imagescan"
+	"github.com/bgdnvk/clanker/internal/k8s/manifestgen"
 	"github.com/bgdnvk/clanker/internal/k8s/plan"
+	"github.com/bgdnvk/clanker/internal/k8s/rollout"
 	"github.com/bgdnvk/clanker/internal/maker"
 	"github.com/bgdnvk/clanker/internal/oracle"
+	"github.com/bgdnvk/clanker/internal/planrun"
+	"github.com/bgdnvk/clanker/internal/planstore"
+	pulumiclient "github.com/bgdnvk/clanker/internal/pulumi"
 	"github.com/bgdnvk/clanker/internal/railway"
+	"github.com/bgdnvk/clanker/internal/reportstore"
 	"github.com/bgdnvk/clanker/internal/resourcedb"
 	"github.com/bgdnvk/clanker/internal/routing"
+	"github.com/bgdnvk/clanker/internal/secrets"
+	"github.com/bgdnvk/clanker/internal/sentry"
+	"github.com/bgdnvk/clanker/internal/sentry/triage"
 	"github.com/bgdnvk/clanker/internal/tencent"
 	tfclient "github.com/bgdnvk/clanker/internal/terraform"
+	"github.com/bgdnvk/clanker/internal/ticket"
 	"github.com/bgdnvk/clanker/internal/vercel"
 	"github.com/bgdnvk/clanker/internal/verda"
 	"github.com/spf13/cobra"
@@ -101,7 +125,7 @@ Examples:
 		}
 		return nil
 	},
-	RunE: func(cmd *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) (runErr error) {
 		question := ""
 		selectedGitHubCodingAgent := ""
 		if len(args) > 0 {
@@ -111,6 +135,7 @@ Examples:
 		// Get context from flags
 		includeAWS, _ := cmd.Flags().GetBool("aws")
 		includeGitHub, _ := cmd.Flags().GetBool("github")
+		includeGitLab, _ := cmd.Flags().GetBool("gitlab")
 		includeCICD, _ := cmd.Flags().GetBool("cicd")
 		includeDB, _ := cmd.Flags().GetBool("db")
 		includeGCP, _ := cmd.Flags().GetBool("gcp")
@@ -128,6 +153,7 @@ Examples:
 		includeObservability, _ := cmd.Flags().GetBool("observability")
 		observabilityRequestedExplicitly := includeObservability
 		includeTerraform, _ := cmd.Flags().GetBool("terraform")
+		includePulumi, _ := cmd.Flags().GetBool("pulumi")
 		includeIAM, _ := cmd.Flags().GetBool("iam")
 		dbConnection, _ := cmd.Flags().GetString("db-connection")
 		iamRoleARN, _ := cmd.Flags().GetString("role-arn")
@@ -137,6 +163,7 @@ Examples:
 		compliance, _ := cmd.Flags().GetBool("compliance")
 		profile, _ := cmd.Flags().GetString("profile")
 		workspace, _ := cmd.Flags().GetString("workspace")
+		pulumiStack, _ := cmd.Flags().GetString("stack")
 		gcpProject, _ := cmd.Flags().GetString("gcp-project")
 		azureSubscription, _ := cmd.Flags().GetString("azure-subscription")
 		aiProfile, _ := cmd.Flags().GetString("ai-profile")
@@ -161,12 +188,30 @@ Examples:
 		destroyer, _ := cmd.Flags().GetBool("destroyer")
 		agentTrace, _ := cmd.Flags().GetBool("agent-trace")
 		if cmd.Flags().Changed("agent-trace") {
-			viper.Set("agent.trace", agentTrace)
+			setFlagOverride("agent.trace", agentTrace)
 		}
 		routeOnly, _ := cmd.Flags().GetBool("route-only")
+		if refreshContext, _ := cmd.Flags().GetBool("refresh-context"); refreshContext {
+			setFlagOverride("context_cache.refresh", true)
+		}
+		if allAccounts, _ := cmd.Flags().GetBool("all-accounts"); allAccounts {
+			setFlagOverride("aws.organization.all_accounts", true)
+			includeAWS = true
+		}
+		if repos, _ := cmd.Flags().GetStringSlice("repo"); len(repos) > 0 {
+			setFlagOverride("github.repos", repos)
+			includeGitHub = true
+		}
+		if createTicket, _ := cmd.Flags().GetString("create-ticket"); createTicket != "" {
+			tracker, err := ticket.ParseTracker(createTicket)
+			if err != nil {
+				return err
+			}
+			setFlagOverride("ask.create_ticket", tracker)
+		}
 
 		if strings.TrimSpace(localModelInferenceURL) != "" {
-			viper.Set("ai.providers.openai.local_model_inference_url", strings.TrimSpace(localModelInferenceURL))
+			setFlagOverride("ai.providers.openai.local_model_inference_url", strings.TrimSpace(localModelInferenceURL))
 		}
 
 		routingQuestion := questionForRouting(question)
@@ -176,7 +221,7 @@ Examples:
 		if includeCICD {
 			includeGitHub = true
 		}
-		if !includeObservability && shouldRouteToObservabilityAgent(routingQuestion) {
+		if !includeObservability && shouldRouteToObservabilityAgent(routingQuestion) && !shouldRouteToSentryAgent(routingQuestion) {
 			includeObservability = true
 		}
 		if strings.TrimSpace(dbConnection) != "" {
@@ -203,15 +248,7 @@ Examples:
 					"reason": "--observability requested logs, traces, metrics, alerts, errors, and warnings context",
 				})
 			}
-			decision := determineRoutingDecisionDetailsWithContext(question, dbConnection)
-			result := map[string]string{
-				"agent":  decision.Agent,
-				"reason": decision.Reason,
-			}
-			if decision.DatabaseMode != "" {
-				result["databaseMode"] = decision.DatabaseMode
-			}
-			return json.NewEncoder(os.Stdout).Encode(result)
+			return json.NewEncoder(os.Stdout).Encode(determineRoutingDecisionDetailsWithContext(question, dbConnection))
 		}
 
 		// Handle explicit --agent flag: delegate to a specific agent
@@ -238,9 +275,19 @@ Examples:
 
 		// Handle apply mode (independent of maker mode)
 		if applyMode {
-			ctx := context.Background()
+			// Ctrl-C stops the apply after killing the running step
+			ctx, stop := planrun.WithInterrupt(context.Background())
+			defer stop()
 			var rawPlan string
-			if planFile != "" {
+			planID, _ := cmd.Flags().GetString("plan-id")
+			if planID != "" {
+				stored, err := planstore.Load(planID)
+				if err != nil {
+					return err
+				}
+				planID = stored.ID
+				rawPlan = string(stored.Body)
+			} else if planFile != "" {
 				data, err := os.ReadFile(planFile)
 				if err != nil {
 					return fmt.Errorf("failed to read plan file: %w", err)
@@ -253,10 +300,49 @@ Examples:
 				}
 				rawPlan = string(data)
 			}
+			if planID == "" {
+				// A plan applied from a file or stdin may be a stored one
+				if stored, err := planstore.FindByBody([]byte(rawPlan)); err == nil && stored != nil {
+					planID = stored.ID
+				}
+			}
+			// Emitting leaves the plan unapplied, so it skips the applied hooks
+			if emitFormat, _ := cmd.Flags().GetString("emit"); emitFormat != "" {
+				emitDir, _ := cmd.Flags().GetString("emit-dir")
+				return emitPlanTerraform(rawPlan, emitFormat, emitDir, debug)
+			}
+			// A dry run applies nothing, so it skips the applied hooks and
+			// leaves a stored plan pending
+			if !dryrun.Enabled() {
+				defer func() {
+					runPlanAppliedHooks("ask --apply", []byte(rawPlan), runErr)
+					notifyPlanApplied("ask --apply", []byte(rawPlan), runErr)
+				}()
+				finishStoredPlan := trackStoredPlanApply(planID, []byte(rawPlan))
+				defer func() { finishStoredPlan(runErr) }()
+			}
+
+			if name, ok := pluginPlanName(rawPlan); ok {
+				return applyPluginPlan(ctx, name, rawPlan, destroyer, debug)
+			}
 
 			// Check if this is a K8s plan (contains helm, eksctl, kubectl, or kubeadm commands)
 			if isK8sPlan(rawPlan) {
-				return executeK8sPlan(ctx, rawPlan, profile, debug)
+				if gitopsMode, _ := cmd.Flags().GetBool("gitops"); gitopsMode {
+					gitopsRepo, _ := cmd.Flags().GetString("gitops-repo")
+					return exportK8sPlanGitOps(ctx, rawPlan, gitopsRepo, debug)
+				}
+				asUser, _ := cmd.Flags().GetString("as")
+				asGroups, _ := cmd.Flags().GetStringSlice("as-group")
+				identity, err := k8s.ParseImpersonation(asUser, asGroups)
+				if err != nil {
+					return err
+				}
+				force, _ := cmd.Flags().GetBool("force")
+				overridePolicy, _ := cmd.Flags().GetBool("override-policy")
+				allowVulnerable, _ := cmd.Flags().GetBool("allow-vulnerable")
+				owner := planOwnershipFromFlags(cmd, planID)
+				return executeK8sPlan(ctx, rawPlan, profile, identity, force, overridePolicy, allowVulnerable, owner, planRevalidationFromFlags(cmd), debug)
 			}
 
 			// Fall back to maker plan execution
@@ -264,6 +350,19 @@ Examples:
 			if err != nil {
 				return fmt.Errorf("invalid plan: %w", err)
 			}
+			reval := planRevalidationFromFlags(cmd)
+			warnIfStalePlan(makerPlan.CreatedAt, reval.MaxAge)
+			planProvider := strings.ToLower(strings.TrimSpace(makerPlan.Provider))
+			if planProvider == "" {
+				planProvider = "aws"
+			}
+			audit.SetPlan(planProvider, []byte(rawPlan), makerPlan.Summary)
+			if dryrun.Enabled() && !dryRunMakerProviders[planProvider] {
+				return fmt.Errorf("--dry-run is not supported for %s plans", planProvider)
+			}
+			if _, err := preflightPlanTools(ctx, makerPlanStepTools(makerPlan), nil, debug); err != nil {
+				return err
+			}
 
 			if strings.EqualFold(strings.TrimSpace(makerPlan.Provider), "gcp") {
 				return maker.ExecuteGCPPlan(ctx, makerPlan, maker.ExecOptions{
@@ -415,6 +514,35 @@ Examples:
 				})
 			}
 
+			if strings.EqualFold(strings.TrimSpace(makerPlan.Provider), "sentry") {
+				return maker.ExecuteSentryPlan(ctx, makerPlan, maker.ExecOptions{
+					SentryAuthToken: sentry.ResolveAuthToken(),
+					SentryOrgSlug:   sentry.ResolveOrgSlug(),
+					SentryHost:      sentry.ResolveHost(),
+					Writer:          os.Stdout,
+					Destroyer:       destroyer,
+					Debug:           debug,
+				})
+			}
+
+			if strings.EqualFold(strings.TrimSpace(makerPlan.Provider), "github") {
+				return maker.ExecuteGitHubPlan(ctx, makerPlan, maker.ExecOptions{
+					GitHubToken: strings.TrimSpace(viper.GetString("github.token")),
+					Writer:      os.Stdout,
+					Destroyer:   destroyer,
+					Debug:       debug,
+				})
+			}
+
+			if strings.EqualFold(strings.TrimSpace(makerPlan.Provider), "gitlab") {
+				return maker.ExecuteGitLabPlan(ctx, makerPlan, maker.ExecOptions{
+					GitLabToken: strings.TrimSpace(viper.GetString("gitlab.token")),
+					Writer:      os.Stdout,
+					Destroyer:   destroyer,
+					Debug:       debug,
+				})
+			}
+
 			if strings.EqualFold(strings.TrimSpace(makerPlan.Provider), "tencent") {
 				tcCreds := tencent.ResolveCredentials()
 				if tcCreds.SecretID == "" || tcCreds.SecretKey == "" {
@@ -494,7 +622,7 @@ Examples:
 				defer resourceStore.Close()
 			}
 
-			return maker.ExecutePlan(ctx, makerPlan, maker.ExecOptions{
+			execOpts := maker.ExecOptions{
 				Profile:       targetProfile,
 				Region:        region,
 				GCPProject:    gcpProject,
@@ -505,7 +633,11 @@ Examples:
 				AIProfile:     aiProfile,
 				Debug:         debug,
 				ResourceStore: resourceStore,
-			})
+			}
+			if err := revalidateAWSMakerPlan(ctx, cmd, makerPlan, execOpts, reval); err != nil {
+				return err
+			}
+			return maker.ExecutePlan(ctx, makerPlan, execOpts)
 		}
 
 		if makerMode {
@@ -641,6 +773,9 @@ Examples:
 				makerProvider = "tencent"
 				makerProviderReason = "explicit"
 			default:
+				if c, ok := pluginForQuestion(questionForRouting(question)); ok {
+					return generatePluginPlan(ctx, c, question, destroyer, debug)
+				}
 				svcCtx := routing.InferContext(questionForRouting(question))
 				if svcCtx.Cloudflare {
 					makerProvider = "cloudflare"
@@ -771,11 +906,16 @@ Examples:
 				if plan.Version == 0 {
 					plan.Version = maker.CurrentPlanVersion
 				}
+				annotateMakerPlanTools(plan)
 				out, err := json.MarshalIndent(plan, "", "  ")
 				if err != nil {
 					return err
 				}
 				fmt.Println(string(out))
+				if err := runPlanGeneratedHooks("ask --maker", out); err != nil {
+					return err
+				}
+				saveGeneratedPlan(planstore.KindMaker, providerLower, "ask --maker", question, plan.Summary, out)
 				return nil
 			}
 
@@ -809,12 +949,17 @@ Examples:
 			if plan.Version == 0 {
 				plan.Version = maker.CurrentPlanVersion
 			}
+			annotateMakerPlanTools(plan)
 
 			out, err := json.MarshalIndent(plan, "", "  ")
 			if err != nil {
 				return err
 			}
 			fmt.Println(string(out))
+			if err := runPlanGeneratedHooks("ask --maker", out); err != nil {
+				return err
+			}
+			saveGeneratedPlan(planstore.KindMaker, "aws", "ask --maker", question, plan.Summary, out)
 			return nil
 		}
 
@@ -929,7 +1074,52 @@ Format as a professional compliance table suitable for government security docum
 			return handleTencentQuery(context.Background(), question, debug)
 		}
 
-		if !includeAWS && !includeGitHub && !includeTerraform && !includeGCP && !includeAzure && !includeCloudflare && !includeDigitalOcean && !includeHetzner && !includeOracle && !includeVercel && !includeFlyio && !includeRailway && !includeVerda && !includeDB {
+		inferContext := !includeAWS && !includeGitHub && !includeGitLab && !includeTerraform && !includePulumi && !includeGCP && !includeAzure && !includeCloudflare && !includeDigitalOcean && !includeHetzner && !includeOracle && !includeVercel && !includeFlyio && !includeRailway && !includeVerda && !includeDB
+		if inferContext {
+			routingQuestion := questionForRouting(question)
+			routedOpts := routedAgentOptions{Debug: debug, Profile: profile, DBConnection: dbConnection, RoleARN: iamRoleARN, PolicyARN: iamPolicyARN, AzureSubscription: azureSubscription}
+			decision := determineRoutingDecisionDetailsWithContext(routingQuestion, dbConnection)
+			switch {
+			case decision.Pinned:
+				recordRoute(routingQuestion, decision.Agent, debug)
+				if handled, err := runRoutedAgent(context.Background(), decision.Agent, routingQuestion, routedOpts); handled {
+					return err
+				}
+				// Pinned provider contexts answer through the general path below
+				switch decision.Agent {
+				case "aws":
+					includeAWS = true
+				case "gcp":
+					includeGCP = true
+				case "azure":
+					includeAzure = true
+				case "github":
+					includeGitHub = true
+				case "terraform":
+					includeTerraform = true
+				case "pulumi":
+					includePulumi = true
+				case "cli":
+					svcCtx := routing.InferContext(routingQuestion)
+					includeAWS, includeGitHub, includeTerraform, includeGCP, includeAzure = svcCtx.AWS, svcCtx.GitHub, svcCtx.Terraform, svcCtx.GCP, svcCtx.Azure
+				}
+				inferContext = false
+			case len(decision.FanOut) > 1:
+				if debug {
+					fmt.Printf("[routing] question spans several agents, fanning out to %s\n", strings.Join(decision.FanOut, ", "))
+				}
+				recordRoute(routingQuestion, strings.Join(decision.FanOut, ","), debug)
+				return runFanOutAgents(context.Background(), decision.FanOut, routingQuestion, routedOpts)
+			default:
+				// Plugin keywords only take part in scored routing
+				if c, ok := findPlugin(decision.Agent); ok {
+					recordRoute(routingQuestion, decision.Agent, debug)
+					return handlePluginQuery(context.Background(), c, routingQuestion, routedOpts)
+				}
+			}
+		}
+
+		if inferContext {
 			routingQuestion := questionForRouting(question)
 
 			// First, do quick keyword check for explicit terms
@@ -972,6 +1162,10 @@ Format as a professional compliance table suitable for government security docum
 				includeTerraform = true
 			}
 
+			if svcCtx.Pulumi {
+				includePulumi = true
+			}
+
 			if svcCtx.GCP {
 				includeGCP = true
 			}
@@ -985,78 +1179,90 @@ Format as a professional compliance table suitable for government security docum
 			includeGitHub = svcCtx.GitHub
 			includeAzure = svcCtx.Azure
 
-			// Handle Cloudflare queries by delegating to Cloudflare agent
-			if svcCtx.Cloudflare {
-				return handleCloudflareQuery(context.Background(), routingQuestion, debug)
-			}
-
-			// Handle Digital Ocean queries
-			if svcCtx.DigitalOcean {
-				return handleDigitalOceanQuery(context.Background(), routingQuestion, debug)
-			}
-
-			// Handle Hetzner queries
-			if svcCtx.Hetzner {
-				return handleHetznerQuery(context.Background(), routingQuestion, debug)
-			}
-
-			// Handle Oracle Cloud queries
-			if svcCtx.Oracle {
-				return handleOracleQuery(context.Background(), routingQuestion, debug)
-			}
-
-			// Handle Vercel queries
-			if svcCtx.Vercel {
-				return handleVercelQuery(context.Background(), routingQuestion, debug)
-			}
-
-			// Handle Fly.io queries
-			if svcCtx.Flyio {
-				return handleFlyioQuery(context.Background(), routingQuestion, debug)
-			}
-
-			// Handle Railway queries
-			if svcCtx.Railway {
-				return handleRailwayQuery(context.Background(), routingQuestion, debug)
-			}
-
-			// Handle Verda queries
-			if svcCtx.Verda {
-				return handleVerdaQuery(cmd.Context(), routingQuestion, debug)
-			}
-
-			// Handle IAM queries by delegating to IAM agent
-			if includeIAM || svcCtx.IAM {
-				return handleIAMQuery(context.Background(), routingQuestion, debug, iamRoleARN, iamPolicyARN)
-			}
-
-			if shouldRouteToObservabilityAgent(routingQuestion) {
-				return handleObservabilityQuery(context.Background(), routingQuestion, debug, profile)
-			}
-
-			if shouldRouteToDatabaseAgentWithContext(routingQuestion, dbConnection) {
-				return handleDatabaseQuery(context.Background(), routingQuestion, debug, dbConnection)
-			}
-
-			if shouldRouteToCICDAgent(routingQuestion) {
-				return handleCICDQuery(context.Background(), routingQuestion, debug)
-			}
-
-			// Handle K8s queries by delegating to K8s agent
-			if svcCtx.K8s {
-				return handleK8sQuery(context.Background(), routingQuestion, debug, viper.GetString("kubernetes.kubeconfig"))
+			// Questions about one provider or domain go to its agent; the rest
+			// are answered below with the inferred provider contexts
+			routedAgent := "cli"
+			switch {
+			// "File a ticket for this" refers to the last report, whatever
+			// it was about
+			case shouldRouteToTicketAgent(routingQuestion):
+				routedAgent = "ticket"
+			// Diagrams draw Kubernetes and RDS together, so "add the new
+			// RDS instance to the diagram" must not go to the RDS agent
+			case shouldRouteToDiagramAgent(routingQuestion):
+				routedAgent = "diagram"
+			// Certificate expiry and DNS truth span Cloudflare, AWS and
+			// more, so they are checked before the provider agents
+			case shouldRouteToCertsAgent(routingQuestion):
+				routedAgent = "certs"
+			case shouldRouteToDNSTruthAgent(routingQuestion):
+				routedAgent = "dns-truth"
+			case svcCtx.Cloudflare:
+				routedAgent = "cloudflare"
+			case svcCtx.DigitalOcean:
+				routedAgent = "digitalocean"
+			case svcCtx.Hetzner:
+				routedAgent = "hetzner"
+			case svcCtx.Oracle:
+				routedAgent = "oracle"
+			case svcCtx.Vercel:
+				routedAgent = "vercel"
+			case svcCtx.Flyio:
+				routedAgent = "flyio"
+			case svcCtx.Railway:
+				routedAgent = "railway"
+			case svcCtx.Verda:
+				routedAgent = "verda"
+			case shouldRouteToAzureInfraAgent(routingQuestion):
+				routedAgent = "azure-infra"
+			case shouldRouteToSecretsAgent(routingQuestion):
+				routedAgent = "secrets"
+			case includeIAM:
+				routedAgent = "iam"
+			case shouldRouteToAWSAuditAgent(routingQuestion):
+				routedAgent = "aws-audit"
+			case svcCtx.IAM, shouldRouteToIAMMutation(routingQuestion):
+				routedAgent = "iam"
+			case shouldRouteToAWSCostAgent(routingQuestion):
+				routedAgent = "aws-cost"
+			case shouldRouteToAWSLogsAgent(routingQuestion):
+				routedAgent = "aws-logs"
+			case shouldRouteToAWSSSMAgent(routingQuestion):
+				routedAgent = "aws-ssm"
+			case shouldRouteToAWSRDSAgent(routingQuestion):
+				routedAgent = "aws-rds"
+			case shouldRouteToAWSLambdaAgent(routingQuestion):
+				routedAgent = "aws-lambda"
+			case shouldRouteToGitHubActionsAgent(routingQuestion):
+				routedAgent = "github-actions"
+			case shouldRouteToPRReviewAgent(routingQuestion):
+				routedAgent = "pr-review"
+			case shouldRouteToGitLabCIAgent(routingQuestion):
+				routedAgent = "gitlab-ci"
+			case shouldRouteToTerraformStateAgent(routingQuestion):
+				routedAgent = "terraform-state"
+			case shouldRouteToSentryAgent(routingQuestion):
+				routedAgent = "sentry"
+			case shouldRouteToObservabilityAgent(routingQuestion):
+				routedAgent = "agent-observability"
+			case shouldRouteToDatabaseAgentWithContext(routingQuestion, dbConnection):
+				routedAgent = "agent-database"
+			case shouldRouteToCICDAgent(routingQuestion):
+				routedAgent = "agent-cicd"
+			case svcCtx.K8s:
+				routedAgent = "k8s"
+			}
+			recordRoute(routingQuestion, routedAgent, debug)
+			if handled, err := runRoutedAgent(context.Background(), routedAgent, routingQuestion, routedAgentOptions{Debug: debug, Profile: profile, DBConnection: dbConnection, RoleARN: iamRoleARN, PolicyARN: iamPolicyARN, AzureSubscription: azureSubscription}); handled {
+				return err
 			}
 		}
 
 		ctx := context.Background()
 
-		// Gather context
-		var awsContext string
-		var githubContext string
-		var terraformContext string
-		var gcpContext string
-		var azureContext string
-		var dbContext string
+		// Build a fetch per provider; they run concurrently below so one slow
+		// or failing provider does not hold up or sink the others
+		var contextFetches []contextFetch
 
 		if includeAWS {
 			var awsClient *aws.Client
@@ -1092,9 +1298,11 @@ Format as a professional compliance table suitable for government security docum
 			}
 
 			// Fall back to local profile if backend credentials not available
+			awsCacheProfile := "backend"
 			if awsClient == nil {
 				// Use specified profile or default from config
 				targetProfile := resolveAWSProfile(profile)
+				awsCacheProfile = targetProfile
 
 				awsClient, err = aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug)
 				if err != nil {
@@ -1102,35 +1310,75 @@ Format as a professional compliance table suitable for government security docum
 				}
 			}
 
-			awsContext, err = awsClient.GetRelevantContext(ctx, routingQuestion)
-			if err != nil {
-				return fmt.Errorf("failed to get AWS context: %w", err)
+			if viper.GetBool("aws.organization.all_accounts") {
+				contextFetches = append(contextFetches, contextFetch{
+					name:    "AWS",
+					timeout: contextTimeout("aws_org", awsOrgContextTimeout),
+					cache:   &contextCacheKey{provider: "aws", profile: awsCacheProfile, question: routingQuestion + " (all accounts)"},
+					fetch: func(ctx context.Context) (string, error) {
+						return awsClient.GetOrgContext(ctx, routingQuestion)
+					},
+				})
+			} else {
+				contextFetches = append(contextFetches, contextFetch{
+					name:    "AWS",
+					timeout: contextTimeout("aws", awsContextTimeout),
+					cache:   &contextCacheKey{provider: "aws", profile: awsCacheProfile, question: routingQuestion},
+					fetch: func(ctx context.Context) (string, error) {
+						return awsClient.GetRelevantContext(ctx, routingQuestion)
+					},
+				})
 			}
-
-			if discovery {
-				rolesContext, err := awsClient.GetRelevantContext(ctx, "iam roles")
-				if err != nil {
-					return fmt.Errorf("failed to get AWS IAM roles context: %w", err)
-				}
-				if strings.TrimSpace(rolesContext) != "" {
-					awsContext = awsContext + rolesContext
-				}
+			if discovery && !viper.GetBool("aws.organization.all_accounts") {
+				// Listed separately so IAM runs alongside the other services
+				// and a roles failure leaves the rest of the AWS context intact
+				contextFetches = append(contextFetches, contextFetch{
+					name:    "AWS IAM roles",
+					timeout: contextTimeout("aws", awsContextTimeout),
+					cache:   &contextCacheKey{provider: "aws", profile: awsCacheProfile, question: "iam roles"},
+					fetch: func(ctx context.Context) (string, error) {
+						return awsClient.GetRelevantContext(ctx, "iam roles")
+					},
+				})
 			}
 		}
 
+		if includeGitHub && useGitLabContext(ctx, routingQuestion) {
+			includeGitHub, includeGitLab = false, true
+		}
+		if includeGitLab {
+			gitlabClient := newGitLabClient("")
+			contextFetches = append(contextFetches, contextFetch{
+				name:    "GitLab",
+				timeout: contextTimeout("gitlab", githubContextTimeout),
+				fetch: func(ctx context.Context) (string, error) {
+					return gitlabClient.GetRelevantContext(ctx, routingQuestion)
+				},
+			})
+		}
+
 		if includeGitHub {
 			// Get GitHub configuration
 			token := viper.GetString("github.token")
 			owner := viper.GetString("github.owner")
 			repo := viper.GetString("github.repo")
 			githubClient := ghclient.NewClient(token, owner, repo)
-			var err error
-			githubContext, err = githubClient.GetRelevantContext(ctx, routingQuestion)
-			if err != nil {
-				if debug {
-					fmt.Printf("warning: failed to get GitHub context: %v\n", err)
-				}
-				githubContext = ""
+			if repos := ghclient.ConfiguredRepos(); len(repos) > 0 {
+				contextFetches = append(contextFetches, contextFetch{
+					name:    "GitHub",
+					timeout: contextTimeout("github_repos", githubReposContextTimeout),
+					fetch: func(ctx context.Context) (string, error) {
+						return githubClient.GetMultiRepoContext(ctx, repos, routingQuestion)
+					},
+				})
+			} else {
+				contextFetches = append(contextFetches, contextFetch{
+					name:    "GitHub",
+					timeout: contextTimeout("github", githubContextTimeout),
+					fetch: func(ctx context.Context) (string, error) {
+						return githubClient.GetRelevantContext(ctx, routingQuestion)
+					},
+				})
 			}
 		}
 
@@ -1154,10 +1402,29 @@ Format as a professional compliance table suitable for government security docum
 					return nil
 				}
 
-				terraformContext, err = tfClient.GetRelevantContext(ctx, routingQuestion)
-				if err != nil {
-					return fmt.Errorf("failed to get Terraform context: %w", err)
+				contextFetches = append(contextFetches, contextFetch{
+					name:    "Terraform",
+					timeout: contextTimeout("terraform", terraformContextTimeout),
+					fetch: func(ctx context.Context) (string, error) {
+						return tfClient.GetRelevantContext(ctx, routingQuestion)
+					},
+				})
+			}
+		}
+
+		if includePulumi {
+			if pulumiClient, err := pulumiclient.NewClient("", pulumiStack); err != nil {
+				if debug {
+					fmt.Printf("Pulumi context requested but unavailable (%v), skipping\n", err)
 				}
+			} else {
+				contextFetches = append(contextFetches, contextFetch{
+					name:    "Pulumi",
+					timeout: contextTimeout("pulumi", pulumiContextTimeout),
+					fetch: func(ctx context.Context) (string, error) {
+						return pulumiClient.GetRelevantContext(ctx, routingQuestion)
+					},
+				})
 			}
 		}
 
@@ -1213,11 +1480,14 @@ Format as a professional compliance table suitable for government security docum
 				}
 			}
 
-			var err error
-			gcpContext, err = gcpClient.GetRelevantContext(ctx, routingQuestion)
-			if err != nil {
-				return fmt.Errorf("failed to get GCP context: %w", err)
-			}
+			contextFetches = append(contextFetches, contextFetch{
+				name:    "GCP",
+				timeout: contextTimeout("gcp", cloudContextTimeout),
+				cache:   &contextCacheKey{provider: "gcp", profile: gcpClient.ProjectID(), question: routingQuestion},
+				fetch: func(ctx context.Context) (string, error) {
+					return gcpClient.GetRelevantContext(ctx, routingQuestion)
+				},
+			})
 		}
 
 		if includeAzure {
@@ -1269,29 +1539,41 @@ Format as a professional compliance table suitable for government security docum
 				}
 			}
 
-			var err error
-			azureContext, err = azureClient.GetRelevantContext(ctx, routingQuestion)
-			if err != nil {
-				return fmt.Errorf("failed to get Azure context: %w", err)
-			}
+			contextFetches = append(contextFetches, contextFetch{
+				name:    "Azure",
+				timeout: contextTimeout("azure", cloudContextTimeout),
+				fetch: func(ctx context.Context) (string, error) {
+					return azureClient.GetRelevantContext(ctx, routingQuestion)
+				},
+			})
 		}
 
 		if includeDB {
-			var err error
-			dbContext, err = dbcontext.BuildRelevantContext(ctx, routingQuestion, dbConnection)
-			if err != nil {
-				if dbRequestedExplicitly {
-					return fmt.Errorf("failed to get database context: %w", err)
-				}
-				if debug {
-					fmt.Printf("warning: failed to get database context: %v\n", err)
-				}
-				dbContext = ""
-			}
+			contextFetches = append(contextFetches, contextFetch{
+				name:     "Database",
+				timeout:  contextTimeout("database", dbContextTimeout),
+				required: dbRequestedExplicitly,
+				fetch: func(ctx context.Context) (string, error) {
+					return dbcontext.BuildRelevantContext(ctx, routingQuestion, dbConnection)
+				},
+			})
+		}
+
+		gathered, unavailableNote, gatherErr := contextResultsByName(contextFetches, gatherContexts(ctx, contextFetches, debug))
+		if gatherErr != nil {
+			return gatherErr
+		}
+		awsContext := gathered["AWS"]
+		if rolesContext := gathered["AWS IAM roles"]; strings.TrimSpace(rolesContext) != "" {
+			awsContext += rolesContext
 		}
+		githubContext := gathered["GitHub"]
+		gcpContext := gathered["GCP"]
+		azureContext := gathered["Azure"]
+		dbContext := gathered["Database"]
 
 		// Only Terraform context is supported here (code scanning disabled).
-		combinedCodeContext := terraformContext
+		combinedCodeContext := gathered["Terraform"]
 		if strings.TrimSpace(gcpContext) != "" {
 			if combinedCodeContext != "" {
 				combinedCodeContext += "\n"
@@ -1310,6 +1592,12 @@ Format as a professional compliance table suitable for government security docum
 			}
 			combinedCodeContext += "Database Context:\n" + dbContext
 		}
+		if unavailableNote != "" {
+			if combinedCodeContext != "" {
+				combinedCodeContext += "\n"
+			}
+			combinedCodeContext += unavailableNote
+		}
 
 		if selectedGitHubCodingAgent != "" {
 			return runGitHubCodingAgentQuery(ctx, selectedGitHubCodingAgent, githubCodingAgentModel, question, awsContext, combinedCodeContext, githubContext)
@@ -1520,6 +1808,8 @@ func init() {
 	askCmd.Flags().Bool("tencent", false, "Include Tencent Cloud infrastructure context")
 	askCmd.Flags().Bool("sre", false, "Use adaptive Clanker SRE discovery context")
 	askCmd.Flags().Bool("github", false, "Include GitHub repository context")
+	askCmd.Flags().Bool("gitlab", false, "Include GitLab project context (pipelines, merge requests, issues)")
+	askCmd.Flags().StringSlice("repo", nil, "GitHub repositories to gather context from, as owner/name or owner/* (repeatable; overrides github.repos)")
 	askCmd.Flags().Bool("cicd", false, "Include CI/CD context (currently GitHub Actions)")
 	askCmd.Flags().Bool("db", false, "Include configured database context")
 	askCmd.Flags().String("db-connection", "", "Database connection name to inspect when using --db")
@@ -1529,11 +1819,15 @@ func init() {
 	askCmd.Flags().String("role-arn", "", "Scope IAM query to a specific role ARN")
 	askCmd.Flags().String("policy-arn", "", "Scope IAM query to a specific policy ARN")
 	askCmd.Flags().Bool("discovery", false, "Run comprehensive infrastructure discovery (all services)")
+	askCmd.Flags().Bool("all-accounts", false, "Gather AWS context from every account in the organization (see aws.organization)")
+	askCmd.Flags().Bool("refresh-context", false, "Re-query cloud inventory instead of reusing ~/.clanker/cache (see context_cache.ttl)")
 	askCmd.Flags().Bool("compliance", false, "Generate compliance report showing all services, ports, and protocols")
 	askCmd.Flags().String("profile", "", "AWS profile to use for infrastructure queries")
 	askCmd.Flags().String("gcp-project", "", "GCP project ID to use for infrastructure queries")
 	askCmd.Flags().String("azure-subscription", "", "Azure subscription ID to use for infrastructure queries")
 	askCmd.Flags().String("workspace", "", "Terraform workspace to use for infrastructure queries")
+	askCmd.Flags().Bool("pulumi", false, "Include Pulumi stack context (stacks, outputs, and a preview for change questions)")
+	askCmd.Flags().String("stack", "", "Pulumi stack to use with --pulumi (default: the project's configured or selected stack)")
 	askCmd.Flags().String("ai-profile", "", "AI profile to use (default: 'default')")
 	askCmd.Flags().String("openai-key", "", "OpenAI API key (overrides config)")
 	askCmd.Flags().String("local-model-inference-url", "", "Local model inference URL for OpenAI-compatible servers (for example http://127.0.0.1:8080/v1)")
@@ -1554,6 +1848,22 @@ func init() {
 	askCmd.Flags().Bool("destroyer", false, "Allow destructive operations when using --maker (requires explicit confirmation in UI/workflow)")
 	askCmd.Flags().Bool("apply", false, "Apply an approved maker plan (reads from stdin unless --plan-file is provided)")
 	askCmd.Flags().String("plan-file", "", "Optional path to maker plan JSON file for --apply")
+	askCmd.Flags().String("plan-id", "", "Apply a stored plan by ID instead of reading --plan-file or stdin (see: clanker plan list)")
+	askCmd.Flags().String("as", "", "Apply K8s plans impersonating this user or service account (sa:<namespace>/<name>)")
+	askCmd.Flags().StringSlice("as-group", nil, "Group to impersonate alongside --as when applying K8s plans (repeatable)")
+	askCmd.Flags().Bool("force", false, "Apply K8s plan manifests even when they fail schema validation or server-side dry-run")
+	askCmd.Flags().Bool("ignore-drift", false, "With --apply, run the plan even when resources it references were deleted or changed since it was generated")
+	askCmd.Flags().Bool("regenerate", false, "With --apply, regenerate a plan that drifted from live state instead of applying it")
+	askCmd.Flags().Duration("max-plan-age", 0, "With --apply, warn when the plan is older than this (default plans.max_age or 24h; 0 disables)")
+	askCmd.Flags().Bool("override-policy", false, "Apply K8s plans that violate ~/.clanker/policy.yaml; the override is recorded in ~/.clanker/audit.log")
+	askCmd.Flags().Bool("allow-vulnerable", false, "Apply K8s plans whose images exceed kubernetes.image_scan.max_critical critical CVEs; recorded in ~/.clanker/audit.log")
+	askCmd.Flags().Bool("prune", false, "After a stored K8s plan applies, delete objects labelled with its ID that it no longer applies")
+	askCmd.Flags().String("replaces", "", "After a stored K8s plan applies, delete objects the given earlier plan applied that this one does not")
+	askCmd.Flags().Bool("gitops", false, "With --apply, open a pull request with the K8s plan as Kustomize/Flux/Argo CD files instead of applying it")
+	askCmd.Flags().String("gitops-repo", "", "GitOps repository (owner/name) for --gitops (default: gitops.repo)")
+	askCmd.Flags().String("emit", "", "With --apply, write the maker or K8s cluster plan as code instead of running it (supported: terraform)")
+	askCmd.Flags().String("emit-dir", defaultEmitDir, "Directory for --emit output; must not already contain the generated files")
+	askCmd.Flags().String("create-ticket", "", "File the diagnosis or audit report as a ticket (linear or notion), linking back to the stored report")
 	askCmd.Flags().Bool("route-only", false, "Return routing decision as JSON without executing (for backend integration)")
 	askCmd.Flags().String("agent", "", "Use a specific agent to handle the query (e.g., hermes, claude-code, database, cicd, observability, software-blocks, data_flow, copilot, codex, claude)")
 	askCmd.Flags().String("github-coding-agent-model", "", "Override the Copilot CLI model used for GitHub coding-agent delegation")
@@ -1775,32 +2085,32 @@ func maybeRunTerraformCommand(ctx context.Context, question string, tfClient *tf
 func applyCommandAIOverrides(aiProfile, openaiKey, anthropicKey, geminiKey, deepseekKey, cohereKey, minimaxKey, openaiModel, anthropicModel, geminiModel, deepseekModel, cohereModel, minimaxModel, githubModel string) {
 	provider := strings.TrimSpace(aiProfile)
 	if provider != "" {
-		viper.Set("ai.default_provider", provider)
+		setFlagOverride("ai.default_provider", provider)
 	} else {
 		provider = strings.TrimSpace(viper.GetString("ai.default_provider"))
 		if provider == "" {
 			provider = "bedrock"
-			viper.Set("ai.default_provider", provider)
+			setFlagOverride("ai.default_provider", provider)
 		}
 	}
 
 	if strings.TrimSpace(openaiKey) != "" {
-		viper.Set("ai.providers.openai.api_key", strings.TrimSpace(openaiKey))
+		setFlagOverride("ai.providers.openai.api_key", strings.TrimSpace(openaiKey))
 	}
 	if strings.TrimSpace(anthropicKey) != "" {
-		viper.Set("ai.providers.anthropic.api_key", strings.TrimSpace(anthropicKey))
+		setFlagOverride("ai.providers.anthropic.api_key", strings.TrimSpace(anthropicKey))
 	}
 	if strings.TrimSpace(geminiKey) != "" {
-		viper.Set("ai.providers.gemini-api.api_key", strings.TrimSpace(geminiKey))
+		setFlagOverride("ai.providers.gemini-api.api_key", strings.TrimSpace(geminiKey))
 	}
 	if strings.TrimSpace(deepseekKey) != "" {
-		viper.Set("ai.providers.deepseek.api_key", strings.TrimSpace(deepseekKey))
+		setFlagOverride("ai.providers.deepseek.api_key", strings.TrimSpace(deepseekKey))
 	}
 	if strings.TrimSpace(cohereKey) != "" {
-		viper.Set("ai.providers.cohere.api_key", strings.TrimSpace(cohereKey))
+		setFlagOverride("ai.providers.cohere.api_key", strings.TrimSpace(cohereKey))
 	}
 	if strings.TrimSpace(minimaxKey) != "" {
-		viper.Set("ai.providers.minimax.api_key", strings.TrimSpace(minimaxKey))
+		setFlagOverride("ai.providers.minimax.api_key", strings.TrimSpace(minimaxKey))
 	}
 
 	maybeOverrideProviderModel(provider, openaiModel, anthropicModel, geminiModel, deepseekModel, cohereModel, minimaxModel, githubModel)
@@ -1810,31 +2120,31 @@ func maybeOverrideProviderModel(provider, openaiModel, anthropicModel, geminiMod
 	switch provider {
 	case "openai":
 		if strings.TrimSpace(openaiModel) != "" {
-			viper.Set("ai.providers.openai.model", strings.TrimSpace(openaiModel))
+			setFlagOverride("ai.providers.openai.model", strings.TrimSpace(openaiModel))
 		}
 	case "anthropic":
 		if strings.TrimSpace(anthropicModel) != "" {
-			viper.Set("ai.providers.anthropic.model", strings.TrimSpace(anthropicModel))
+			setFlagOverride("ai.providers.anthropic.model", strings.TrimSpace(anthropicModel))
 		}
 	case "gemini", "gemini-api":
 		if model := resolveGeminiModel(provider, geminiModel); model != "" {
-			viper.Set(fmt.Sprintf("ai.providers.%s.model", provider), model)
+			setFlagOverride(fmt.Sprintf("ai.providers.%s.model", provider), model)
 		}
 	case "deepseek":
 		if strings.TrimSpace(deepseekModel) != "" {
-			viper.Set("ai.providers.deepseek.model", strings.TrimSpace(deepseekModel))
+			setFlagOverride("ai.providers.deepseek.model", strings.TrimSpace(deepseekModel))
 		}
 	case "cohere":
 		if strings.TrimSpace(cohereModel) != "" {
-			viper.Set("ai.providers.cohere.model", strings.TrimSpace(cohereModel))
+			setFlagOverride("ai.providers.cohere.model", strings.TrimSpace(cohereModel))
 		}
 	case "minimax":
 		if strings.TrimSpace(minimaxModel) != "" {
-			viper.Set("ai.providers.minimax.model", strings.TrimSpace(minimaxModel))
+			setFlagOverride("ai.providers.minimax.model", strings.TrimSpace(minimaxModel))
 		}
 	case "github-models":
 		if strings.TrimSpace(githubModel) != "" {
-			viper.Set("ai.providers.github-models.model", strings.TrimSpace(githubModel))
+			setFlagOverride("ai.providers.github-models.model", strings.TrimSpace(githubModel))
 		}
 	}
 }
@@ -1948,6 +2258,16 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 		fmt.Println("Delegating query to Cloudflare agent...")
 	}
 
+	client, err := newCloudflareAgentClient(ctx, debug)
+	if err != nil {
+		return err
+	}
+	return runCloudflareSubAgent(ctx, client, cloudflareSubAgentFor(question), question, debug)
+}
+
+// newCloudflareAgentClient builds a Cloudflare client from backend
+// credentials when available, falling back to the local config
+func newCloudflareAgentClient(ctx context.Context, debug bool) (*cloudflare.Client, error) {
 	var client *cloudflare.Client
 	var err error
 
@@ -1965,7 +2285,7 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 				AccountID: backendCreds.AccountID,
 			}, debug)
 			if err != nil {
-				return fmt.Errorf("failed to create Cloudflare client with backend credentials: %w", err)
+				return nil, fmt.Errorf("failed to create Cloudflare client with backend credentials: %w", err)
 			}
 		} else if debug {
 			fmt.Printf("[backend] No Cloudflare credentials available (%v), falling back to local\n", backendErr)
@@ -1978,18 +2298,28 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 		apiToken := cloudflare.ResolveAPIToken()
 
 		if apiToken == "" {
-			return fmt.Errorf("cloudflare api_token is required (set cloudflare.api_token, CLOUDFLARE_API_TOKEN, or CF_API_TOKEN)")
+			return nil, fmt.Errorf("cloudflare api_token is required (set cloudflare.api_token, CLOUDFLARE_API_TOKEN, or CF_API_TOKEN)")
 		}
 
 		client, err = cloudflare.NewClient(accountID, apiToken, debug)
 		if err != nil {
-			return fmt.Errorf("failed to create Cloudflare client: %w", err)
+			return nil, fmt.Errorf("failed to create Cloudflare client: %w", err)
 		}
 	}
+	return client, nil
+}
 
-	// Determine query type
+// cloudflareSubAgentFor picks the Cloudflare sub-agent for a question: waf,
+// workers, analytics, zerotrust or dns, or "" for the general agent
+func cloudflareSubAgentFor(question string) string {
 	questionLower := strings.ToLower(question)
 
+	// Blocked requests and other firewall events are analytics, not WAF
+	// configuration
+	if cfanalytics.IsSecurityEventQuery(question) {
+		return "analytics"
+	}
+
 	// Check for WAF/Security queries
 	isWAF := strings.Contains(questionLower, "firewall") ||
 		strings.Contains(questionLower, "waf") ||
@@ -2000,6 +2330,68 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 		strings.Contains(questionLower, "bot")
 
 	if isWAF {
+		return "waf"
+	}
+
+	// Check for Workers queries
+	isWorkers := strings.Contains(questionLower, "worker") ||
+		strings.Contains(questionLower, "kv") ||
+		strings.Contains(questionLower, "d1") ||
+		strings.Contains(questionLower, "r2") ||
+		strings.Contains(questionLower, "pages") ||
+		strings.Contains(questionLower, "durable object")
+
+	if isWorkers {
+		return "workers"
+	}
+
+	// Check for Analytics queries
+	isAnalytics := strings.Contains(questionLower, "analytics") ||
+		strings.Contains(questionLower, "traffic") ||
+		strings.Contains(questionLower, "bandwidth") ||
+		strings.Contains(questionLower, "requests") ||
+		strings.Contains(questionLower, "visitors") ||
+		strings.Contains(questionLower, "page views") ||
+		strings.Contains(questionLower, "performance metrics")
+
+	if isAnalytics {
+		return "analytics"
+	}
+
+	// Check for Zero Trust queries
+	isZeroTrust := strings.Contains(questionLower, "tunnel") ||
+		strings.Contains(questionLower, "access app") ||
+		strings.Contains(questionLower, "access policy") ||
+		strings.Contains(questionLower, "zero trust") ||
+		strings.Contains(questionLower, "cloudflared") ||
+		strings.Contains(questionLower, "warp")
+
+	if isZeroTrust {
+		return "zerotrust"
+	}
+
+	// Check for DNS queries
+	isDNS := strings.Contains(questionLower, "dns") ||
+		strings.Contains(questionLower, "record") ||
+		strings.Contains(questionLower, "zone") ||
+		strings.Contains(questionLower, "domain") ||
+		strings.Contains(questionLower, "cname") ||
+		strings.Contains(questionLower, "a record") ||
+		strings.Contains(questionLower, "mx") ||
+		strings.Contains(questionLower, "txt") ||
+		strings.Contains(questionLower, "nameserver")
+
+	if isDNS {
+		return "dns"
+	}
+	return ""
+}
+
+// runCloudflareSubAgent runs one Cloudflare sub-agent; "" runs the general
+// agent over the account context
+func runCloudflareSubAgent(ctx context.Context, client *cloudflare.Client, subAgent, question string, debug bool) error {
+	switch subAgent {
+	case "waf":
 		// Use WAF subagent
 		wafAgent := cfwaf.NewSubAgent(client, debug)
 		opts := cfwaf.QueryOptions{}
@@ -2016,6 +2408,9 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 				return fmt.Errorf("failed to format plan: %w", err)
 			}
 			fmt.Println(string(planJSON))
+			if err := runPlanGeneratedHooks("ask cloudflare waf", planJSON); err != nil {
+				return err
+			}
 			fmt.Println("\n// To apply this plan, run:")
 			fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
 		case cfwaf.ResponseTypeResult:
@@ -2024,17 +2419,7 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 			return response.Error
 		}
 		return nil
-	}
-
-	// Check for Workers queries
-	isWorkers := strings.Contains(questionLower, "worker") ||
-		strings.Contains(questionLower, "kv") ||
-		strings.Contains(questionLower, "d1") ||
-		strings.Contains(questionLower, "r2") ||
-		strings.Contains(questionLower, "pages") ||
-		strings.Contains(questionLower, "durable object")
-
-	if isWorkers {
+	case "workers":
 		// Use Workers subagent
 		workersAgent := cfworkers.NewSubAgent(client, debug)
 		opts := cfworkers.QueryOptions{
@@ -2053,6 +2438,9 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 				return fmt.Errorf("failed to format plan: %w", err)
 			}
 			fmt.Println(string(planJSON))
+			if err := runPlanGeneratedHooks("ask cloudflare workers", planJSON); err != nil {
+				return err
+			}
 			fmt.Println("\n// To apply this plan, run:")
 			fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
 		case cfworkers.ResponseTypeResult:
@@ -2061,18 +2449,13 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 			return response.Error
 		}
 		return nil
-	}
-
-	// Check for Analytics queries
-	isAnalytics := strings.Contains(questionLower, "analytics") ||
-		strings.Contains(questionLower, "traffic") ||
-		strings.Contains(questionLower, "bandwidth") ||
-		strings.Contains(questionLower, "requests") ||
-		strings.Contains(questionLower, "visitors") ||
-		strings.Contains(questionLower, "page views") ||
-		strings.Contains(questionLower, "performance metrics")
+	case "analytics":
+		if cfanalytics.IsSecurityEventQuery(question) {
+			if q := cfanalytics.ParseEventQuery(question); q.Tail {
+				return tailCloudflareSecurityEvents(ctx, client, q, cfanalytics.DefaultTailInterval, debug)
+			}
+		}
 
-	if isAnalytics {
 		// Use Analytics subagent
 		analyticsAgent := cfanalytics.NewSubAgent(client, debug)
 		opts := cfanalytics.QueryOptions{}
@@ -2089,17 +2472,7 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 			return response.Error
 		}
 		return nil
-	}
-
-	// Check for Zero Trust queries
-	isZeroTrust := strings.Contains(questionLower, "tunnel") ||
-		strings.Contains(questionLower, "access app") ||
-		strings.Contains(questionLower, "access policy") ||
-		strings.Contains(questionLower, "zero trust") ||
-		strings.Contains(questionLower, "cloudflared") ||
-		strings.Contains(questionLower, "warp")
-
-	if isZeroTrust {
+	case "zerotrust":
 		// Use Zero Trust subagent
 		ztAgent := cfzerotrust.NewSubAgent(client, debug)
 		opts := cfzerotrust.QueryOptions{
@@ -2118,6 +2491,9 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 				return fmt.Errorf("failed to format plan: %w", err)
 			}
 			fmt.Println(string(planJSON))
+			if err := runPlanGeneratedHooks("ask cloudflare zero trust", planJSON); err != nil {
+				return err
+			}
 			fmt.Println("\n// To apply this plan, run:")
 			fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
 		case cfzerotrust.ResponseTypeResult:
@@ -2126,20 +2502,12 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 			return response.Error
 		}
 		return nil
-	}
-
-	// Check for DNS queries
-	isDNS := strings.Contains(questionLower, "dns") ||
-		strings.Contains(questionLower, "record") ||
-		strings.Contains(questionLower, "zone") ||
-		strings.Contains(questionLower, "domain") ||
-		strings.Contains(questionLower, "cname") ||
-		strings.Contains(questionLower, "a record") ||
-		strings.Contains(questionLower, "mx") ||
-		strings.Contains(questionLower, "txt") ||
-		strings.Contains(questionLower, "nameserver")
+	case "dns":
+		// Zone file imports need the file, so they skip the query path
+		if req, ok := cfdns.ParseImportQuestion(question); ok {
+			return handleCloudflareDNSImport(ctx, client, req, "ask cloudflare dns", question, debug)
+		}
 
-	if isDNS {
 		// Use DNS subagent
 		dnsAgent := cfdns.NewSubAgent(client, debug)
 		opts := cfdns.QueryOptions{}
@@ -2156,7 +2524,10 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 				return fmt.Errorf("failed to format plan: %w", err)
 			}
 			fmt.Println(string(planJSON))
-			fmt.Println("\n// To apply this plan, run:")
+			if err := runPlanGeneratedHooks("ask cloudflare dns", planJSON); err != nil {
+				return err
+			}
+			fmt.Println("\n// To apply this plan, run:")
 			fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
 		case cfdns.ResponseTypeResult:
 			fmt.Println(response.Result)
@@ -2166,8 +2537,10 @@ func handleCloudflareQuery(ctx context.Context, question string, debug bool) err
 		return nil
 	}
 
-	// For non-DNS queries, use the general Cloudflare context
-	cfContext, err := client.GetRelevantContext(ctx, question)
+	// Anything else uses the general Cloudflare context
+	cfContext, err := fetchWithContextCache(ctx, &contextCacheKey{provider: "cloudflare", profile: client.GetAccountID(), question: question}, func(ctx context.Context) (string, error) {
+		return client.GetRelevantContext(ctx, question)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to get Cloudflare context: %w", err)
 	}
@@ -2252,16 +2625,38 @@ func handleIAMQuery(ctx context.Context, question string, debug bool, roleARN, p
 		awsRegion = "us-east-1"
 	}
 
+	// The AWS client is only built when an operation needs the CLI
+	awsCLI := sync.OnceValues(func() (*aws.Client, error) {
+		return aws.NewClientWithProfileAndDebug(ctx, awsProfile, debug)
+	})
+
 	// Create IAM agent
 	iamAgent, err := iamclient.NewAgentWithOptions(iamclient.AgentOptions{
 		Profile: awsProfile,
 		Region:  awsRegion,
 		Debug:   debug,
+		CLI: func(ctx context.Context, args []string) (string, error) {
+			client, err := awsCLI()
+			if err != nil {
+				return "", fmt.Errorf("failed to create AWS client with profile %s: %w", awsProfile, err)
+			}
+			return client.ExecCLI(ctx, args)
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create IAM agent: %w", err)
 	}
 
+	// Changes are planned here and applied with ask --apply, which audits
+	// each step
+	if mutation := iamclient.ParseMutation(question); mutation.Op != iamclient.MutationNone {
+		plan, err := iamAgent.PlanMutation(ctx, mutation, question, time.Now())
+		if err != nil {
+			return err
+		}
+		return printIAMPlan(question, plan)
+	}
+
 	// Configure query options - scope to specific resource if ARN provided
 	opts := iamclient.QueryOptions{
 		AccountWide: roleARN == "" && policyARN == "",
@@ -2278,12 +2673,23 @@ func handleIAMQuery(ctx context.Context, question string, debug bool, roleARN, p
 	// Output based on response type
 	switch response.Type {
 	case iamclient.ResponseTypePlan:
-		// Output plan
 		fmt.Println(response.Content)
-		fmt.Println("\n// To apply this plan, review the commands and run them manually")
+		plan, err := response.Plan.MakerPlan(question, time.Now())
+		if err != nil {
+			return err
+		}
+		if plan == nil {
+			return nil
+		}
+		fmt.Println()
+		return printIAMPlan(question, plan)
 
 	case iamclient.ResponseTypeFindings:
 		fmt.Println(response.Content)
+		if response.Remediation != nil {
+			fmt.Println()
+			return printIAMPlan(question, response.Remediation)
+		}
 
 	case iamclient.ResponseTypeResult:
 		fmt.Println(response.Content)
@@ -2295,6 +2701,600 @@ func handleIAMQuery(ctx context.Context, question string, debug bool, roleARN, p
 	return nil
 }
 
+// printIAMPlan prints an IAM change plan and stores it for ask --apply
+func printIAMPlan(question string, plan *maker.Plan) error {
+	planJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", plan.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks("ask iam", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, "aws", "ask iam", question, plan.Summary, planJSON)
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
+
+// shouldRouteToIAMMutation reports whether a question asks for an IAM
+// change the IAM agent can plan
+func shouldRouteToIAMMutation(question string) bool {
+	if iamclient.ParseMutation(question).Op == iamclient.MutationNone {
+		return false
+	}
+	svcCtx := routing.InferContext(question)
+	return !svcCtx.K8s && !svcCtx.GCP && !svcCtx.Azure && !svcCtx.Cloudflare && !svcCtx.DigitalOcean && !svcCtx.Hetzner && !svcCtx.Oracle
+}
+
+// shouldRouteToAWSCostAgent reports whether a question asks what AWS spend
+// was, when AWS is the provider it would be answered for
+func shouldRouteToAWSCostAgent(question string) bool {
+	if !awscost.IsCostQuestion(question) {
+		return false
+	}
+	svcCtx := routing.InferContext(question)
+	if svcCtx.GCP || svcCtx.Azure || svcCtx.Cloudflare || svcCtx.DigitalOcean || svcCtx.Hetzner || svcCtx.Oracle {
+		return false
+	}
+	return svcCtx.AWS || routing.DefaultInfraProvider() == "aws"
+}
+
+// handleAWSCostQuery answers an AWS spend question from Cost Explorer
+func handleAWSCostQuery(ctx context.Context, question string, debug bool, profile string) error {
+	targetProfile := resolveAWSProfile(profile)
+	if debug {
+		fmt.Printf("Delegating query to AWS cost agent (profile %s)...\n", targetProfile)
+	}
+	awsClient, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client with profile %s: %w", targetProfile, err)
+	}
+
+	report, err := awscost.NewAgent(awsClient.ExecCLI, debug).HandleQuery(ctx, question, time.Now())
+	if err != nil {
+		return fmt.Errorf("AWS cost agent error: %w", err)
+	}
+	fmt.Print(report)
+	return nil
+}
+
+// shouldRouteToAWSAuditAgent reports whether a question asks for a security
+// posture audit of an AWS account
+func shouldRouteToAWSAuditAgent(question string) bool {
+	if !posture.IsAuditQuestion(question) {
+		return false
+	}
+	svcCtx := routing.InferContext(question)
+	if svcCtx.K8s || svcCtx.GCP || svcCtx.Azure || svcCtx.Cloudflare || svcCtx.DigitalOcean || svcCtx.Hetzner || svcCtx.Oracle {
+		return false
+	}
+	return svcCtx.AWS || routing.DefaultInfraProvider() == "aws"
+}
+
+// handleAWSAuditQuery audits the account's security posture, prints the
+// severity-ranked report and, when some findings can be fixed from the CLI,
+// a remediation plan
+func handleAWSAuditQuery(ctx context.Context, question string, debug bool, profile string) error {
+	targetProfile := resolveAWSProfile(profile)
+	if debug {
+		fmt.Printf("Delegating query to AWS security audit agent (profile %s)...\n", targetProfile)
+	}
+	awsClient, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client with profile %s: %w", targetProfile, err)
+	}
+
+	report := posture.NewAgent(awsClient.ExecCLI, debug).Audit(ctx)
+	text := report.Format()
+	fmt.Print(text)
+	stored := saveReport(reportstore.KindAWSAudit, "ask aws-audit", question, fmt.Sprintf("%d findings", len(report.Findings)), text, report)
+	if err := fileRequestedTicket(ctx, stored, debug); err != nil {
+		return err
+	}
+
+	plan := report.RemediationPlan(question)
+	if plan == nil {
+		return nil
+	}
+	planJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Println("\nRemediation plan:")
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks("ask aws-audit", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, "aws", "ask aws-audit", question, plan.Summary, planJSON)
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
+
+// shouldRouteToAWSLogsAgent reports whether a question asks to read the
+// logs of a named AWS log group or Lambda function
+func shouldRouteToAWSLogsAgent(question string) bool {
+	if !awslogs.IsLogsQuestion(question) {
+		return false
+	}
+	svcCtx := routing.InferContext(question)
+	if svcCtx.K8s || svcCtx.GCP || svcCtx.Azure || svcCtx.Cloudflare || svcCtx.DigitalOcean || svcCtx.Hetzner || svcCtx.Oracle {
+		return false
+	}
+	return svcCtx.AWS || routing.DefaultInfraProvider() == "aws"
+}
+
+// awsLogsOptions override what the AWS logs agent reads from the question
+type awsLogsOptions struct {
+	LogGroups []string
+	Since     time.Duration
+	Query     string
+}
+
+// handleAWSLogsQuery answers a logs question with a CloudWatch Logs
+// Insights query and prints the query alongside the results
+func handleAWSLogsQuery(ctx context.Context, question string, debug bool, profile string, opts awsLogsOptions) error {
+	targetProfile := resolveAWSProfile(profile)
+	if debug {
+		fmt.Printf("Delegating query to AWS logs agent (profile %s)...\n", targetProfile)
+	}
+	awsClient, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client with profile %s: %w", targetProfile, err)
+	}
+
+	now := time.Now()
+	q := awslogs.ParseQuery(question, now)
+	if len(opts.LogGroups) > 0 {
+		q.SetLogGroups(opts.LogGroups)
+	}
+	if opts.Since > 0 {
+		q.SetSince(opts.Since, now)
+	}
+	if strings.TrimSpace(opts.Query) != "" {
+		q.SetQueryString(opts.Query)
+	}
+
+	result, err := awslogs.NewAgent(awsClient.ExecCLI, debug).Run(ctx, q)
+	if err != nil {
+		return fmt.Errorf("AWS logs agent error: %w", err)
+	}
+	fmt.Print(awslogs.Format(result, question))
+	return nil
+}
+
+// shouldRouteToAWSRDSAgent reports whether a question names RDS or Aurora
+// and asks to list, snapshot, restore or resize instances or about their
+// maintenance
+func shouldRouteToAWSRDSAgent(question string) bool {
+	if !awsrds.IsRDSQuestion(question) {
+		return false
+	}
+	// RDS and Aurora are AWS-only names, so no AWS context is required, and
+	// "cluster" must not send Aurora questions to Kubernetes
+	svcCtx := routing.InferContext(question)
+	return !svcCtx.GCP && !svcCtx.Azure && !svcCtx.DigitalOcean && !svcCtx.Hetzner && !svcCtx.Oracle
+}
+
+// handleAWSRDSQuery lists RDS instances and clusters with their metrics or
+// pending maintenance, or prints a plan for a snapshot, point-in-time
+// restore or instance class change. Plans are never applied here.
+func handleAWSRDSQuery(ctx context.Context, question string, debug bool, profile string) error {
+	targetProfile := resolveAWSProfile(profile)
+	if debug {
+		fmt.Printf("Delegating query to AWS RDS agent (profile %s)...\n", targetProfile)
+	}
+	awsClient, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client with profile %s: %w", targetProfile, err)
+	}
+
+	agent := awsrds.NewAgent(awsClient.ExecCLI, debug)
+	now := time.Now()
+	req := awsrds.ParseRequest(question, now)
+	switch req.Op {
+	case awsrds.List:
+		inv, err := agent.List(ctx, now)
+		if err != nil {
+			return fmt.Errorf("AWS RDS agent error: %w", err)
+		}
+		fmt.Print(inv.Format())
+		return nil
+	case awsrds.Maintenance:
+		report, err := agent.Maintenance(ctx)
+		if err != nil {
+			return fmt.Errorf("AWS RDS agent error: %w", err)
+		}
+		fmt.Print(report.Format())
+		return nil
+	}
+
+	plan, err := agent.Plan(ctx, req, question, now)
+	if err != nil {
+		return err
+	}
+	planJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", plan.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks("ask aws-rds", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, "aws", "ask aws-rds", question, plan.Summary, planJSON)
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
+
+// shouldRouteToAWSLambdaAgent reports whether a question asks to list
+// Lambda functions, see a function's errors, change its environment or
+// deploy it. Log questions for a named function stay with the logs agent.
+func shouldRouteToAWSLambdaAgent(question string) bool {
+	if !awslambda.IsLambdaQuestion(question) {
+		return false
+	}
+	if awslambda.ParseRequest(question).Op == awslambda.Errors && shouldRouteToAWSLogsAgent(question) {
+		return false
+	}
+	svcCtx := routing.InferContext(question)
+	return !svcCtx.GCP && !svcCtx.Azure && !svcCtx.DigitalOcean && !svcCtx.Hetzner && !svcCtx.Oracle
+}
+
+// handleAWSLambdaQuery lists Lambda functions with their error rates, shows
+// a function's recent errors, or prints a plan for an environment change or
+// a deploy. Plans are never applied here.
+func handleAWSLambdaQuery(ctx context.Context, question string, debug bool, profile string) error {
+	targetProfile := resolveAWSProfile(profile)
+	if debug {
+		fmt.Printf("Delegating query to AWS Lambda agent (profile %s)...\n", targetProfile)
+	}
+	awsClient, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug)
+	if err != nil {
+		return fmt.Errorf("failed to create AWS client with profile %s: %w", targetProfile, err)
+	}
+
+	agent := awslambda.NewAgent(awsClient.ExecCLI, debug)
+	now := time.Now()
+	req := awslambda.ParseRequest(question)
+	switch req.Op {
+	case awslambda.List:
+		list, err := agent.List(ctx, awslambda.DefaultWindow, now)
+		if err != nil {
+			return fmt.Errorf("AWS Lambda agent error: %w", err)
+		}
+		fmt.Print(list.Format())
+		return nil
+	case awslambda.Errors:
+		return printAWSLambdaErrors(ctx, awsClient, agent, req.Function, question, debug, now)
+	}
+
+	plan, err := agent.Plan(ctx, req, question, now)
+	if err != nil {
+		return err
+	}
+	planJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", plan.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks("ask aws-lambda", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, "aws", "ask aws-lambda", question, plan.Summary, planJSON)
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
+
+// printAWSLambdaErrors prints a function's error count over the window the
+// question asks about, then its failed invocations from a Logs Insights
+// query on the function's log group
+func printAWSLambdaErrors(ctx context.Context, awsClient *aws.Client, agent *awslambda.Agent, name, question string, debug bool, now time.Time) error {
+	f, err := agent.Function(ctx, name)
+	if err != nil {
+		return err
+	}
+	q := awslogs.ParseQuery(question, now)
+	q.SetLogGroups([]string{f.LogGroup})
+	q.SetQueryString(awslambda.ErrorsQuery)
+	if err := agent.Activity(ctx, &f, q.End.Sub(q.Start), now); err != nil && debug {
+		fmt.Printf("[aws-lambda] %v\n", err)
+	}
+	fmt.Printf("%s (%s)\n\n", f.Summary(), q.Label)
+
+	result, err := awslogs.NewAgent(awsClient.ExecCLI, debug).Run(ctx, q)
+	if err != nil {
+		return fmt.Errorf("AWS Lambda agent error: %w", err)
+	}
+	fmt.Print(awslogs.Format(result, question))
+	return nil
+}
+
+// shouldRouteToSecretsAgent reports whether a question asks to list, rotate
+// or create a secret in Secrets Manager, Parameter Store, GCP Secret Manager
+// or Kubernetes, or which workloads read one
+func shouldRouteToSecretsAgent(question string) bool {
+	if !secrets.IsSecretsQuestion(question) {
+		return false
+	}
+	svcCtx := routing.InferContext(question)
+	return !svcCtx.Azure && !svcCtx.DigitalOcean && !svcCtx.Hetzner && !svcCtx.Oracle && !svcCtx.Cloudflare &&
+		!svcCtx.Flyio && !svcCtx.Vercel && !svcCtx.Railway
+}
+
+// secretsRunners reaches AWS through the profile, GCP through the resolved
+// project and Kubernetes through the configured kubeconfig. A provider that
+// cannot be reached is left out.
+func secretsRunners(ctx context.Context, profile string, debug bool) secrets.Runners {
+	var runners secrets.Runners
+	targetProfile := resolveAWSProfile(profile)
+	if awsClient, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug); err == nil {
+		runners.AWS = awsClient.ExecCLI
+	} else if debug {
+		fmt.Printf("[secrets] AWS profile %s unavailable: %v\n", targetProfile, err)
+	}
+	if projectID := strings.TrimSpace(gcp.ResolveProjectID()); projectID != "" {
+		if gcpClient, err := gcp.NewClient(projectID, debug); err == nil {
+			runners.GCloud = gcpClient.ExecCLI
+		} else if debug {
+			fmt.Printf("[secrets] GCP project %s unavailable: %v\n", projectID, err)
+		}
+	}
+	k8sClient := k8s.NewClient(viper.GetString("kubernetes.kubeconfig"), "", debug)
+	runners.Kubectl = func(ctx context.Context, args []string) (string, error) {
+		return k8sClient.Run(ctx, args...)
+	}
+	return runners
+}
+
+// handleSecretsQuery lists secrets by their metadata, finds the workloads
+// that read one, or prints a plan to rotate or create one. AWS and GCP
+// plans are saved for clanker ask --apply; Kubernetes changes are printed
+// as kubectl steps. Values are never printed.
+func handleSecretsQuery(ctx context.Context, question string, debug bool, profile string) error {
+	if debug {
+		fmt.Println("Delegating query to secrets agent...")
+	}
+	agent := secrets.NewAgent(secretsRunners(ctx, profile, debug), debug)
+	now := time.Now()
+	req := secrets.ParseRequest(question)
+	switch req.Op {
+	case secrets.List:
+		fmt.Print(agent.List(ctx, req.Stores, req.Namespace).Format(now))
+		return nil
+	case secrets.References:
+		fmt.Print(agent.References(ctx, req.Name, req.Namespace).Format())
+		return nil
+	}
+
+	change, err := agent.Plan(ctx, req, question, now)
+	if err != nil {
+		return err
+	}
+	if change.Plan == nil {
+		fmt.Print(change.Format())
+		return nil
+	}
+	planJSON, err := json.MarshalIndent(change.Plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", change.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks("ask secrets", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, change.Plan.Provider, "ask secrets", question, change.Summary, planJSON)
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
+
+// shouldRouteToCertsAgent reports whether a question asks which TLS
+// certificates expire, or for an inventory of them, on a provider the
+// certificate agent reads
+func shouldRouteToCertsAgent(question string) bool {
+	if !certs.IsCertsQuestion(question) {
+		return false
+	}
+	svcCtx := routing.InferContext(question)
+	return !svcCtx.Azure && !svcCtx.DigitalOcean && !svcCtx.Hetzner && !svcCtx.Oracle &&
+		!svcCtx.Vercel && !svcCtx.Flyio && !svcCtx.Railway && !svcCtx.Verda
+}
+
+// certsRunners reaches ACM through the profile, Cloudflare through the
+// configured token, GCP through the resolved project and cert-manager
+// through the configured kubeconfig. A source that cannot be reached is
+// left out.
+func certsRunners(ctx context.Context, profile string, debug bool) certs.Runners {
+	var runners certs.Runners
+	targetProfile := resolveAWSProfile(profile)
+	if awsClient, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug); err == nil {
+		runners.AWS = awsClient.ExecCLI
+	} else if debug {
+		fmt.Printf("[certs] AWS profile %s unavailable: %v\n", targetProfile, err)
+	}
+	if cfClient, err := newCloudflareAgentClient(ctx, debug); err == nil {
+		runners.Cloudflare = func(ctx context.Context, args []string) (string, error) {
+			return cfClient.RunAPIWithContext(ctx, args[0], args[1], "")
+		}
+	} else if debug {
+		fmt.Printf("[certs] Cloudflare unavailable: %v\n", err)
+	}
+	if projectID := strings.TrimSpace(gcp.ResolveProjectID()); projectID != "" {
+		if gcpClient, err := gcp.NewClient(projectID, debug); err == nil {
+			runners.GCloud = gcpClient.ExecCLI
+		} else if debug {
+			fmt.Printf("[certs] GCP project %s unavailable: %v\n", projectID, err)
+		}
+	}
+	k8sClient := k8s.NewClient(viper.GetString("kubernetes.kubeconfig"), "", debug)
+	runners.Kubectl = func(ctx context.Context, args []string) (string, error) {
+		return k8sClient.Run(ctx, args...)
+	}
+	return runners
+}
+
+// handleCertsQuery prints one report of certificates across ACM,
+// Cloudflare, GCP and cert-manager sorted by days left, followed by the
+// renewals of those that will not renew on their own. ACM and GCP renewals
+// are saved as plans for clanker ask --apply; the rest are steps and notes.
+func handleCertsQuery(ctx context.Context, question string, debug bool, profile string) error {
+	if debug {
+		fmt.Println("Delegating query to certificate agent...")
+	}
+	agent := certs.NewAgent(certsRunners(ctx, profile, debug), debug)
+	now := time.Now()
+	req := certs.ParseRequest(question)
+	report := agent.List(ctx, req.Sources)
+	fmt.Print(report.Format(now, req.Within, req.Expiring))
+
+	renewals := agent.Renewals(ctx, report, req.Within, question, now)
+	if text := renewals.Format(); text != "" {
+		fmt.Printf("\n%s", text)
+	}
+	for _, plan := range renewals.Plans {
+		planJSON, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format plan: %w", err)
+		}
+		fmt.Printf("\n%s\n\n", plan.Summary)
+		fmt.Println(string(planJSON))
+		if err := runPlanGeneratedHooks("ask certs", planJSON); err != nil {
+			return err
+		}
+		saveGeneratedPlan(planstore.KindMaker, plan.Provider, "ask certs", question, plan.Summary, planJSON)
+		fmt.Println("\n// To apply this plan, run:")
+		fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	}
+	return nil
+}
+
+// shouldRouteToDNSTruthAgent reports whether a question asks where a name
+// resolves, who serves it, or which of its records conflict or dangle
+func shouldRouteToDNSTruthAgent(question string) bool {
+	return dnstruth.IsDNSTruthQuestion(question)
+}
+
+// handleDNSTruthQuery resolves a name live and cross-references it with
+// the Route53 and Cloudflare zones that could hold it. When the question
+// asks for a fix, dangling records in the serving zone become plans for
+// clanker ask --apply.
+func handleDNSTruthQuery(ctx context.Context, question string, debug bool, profile string) error {
+	if debug {
+		fmt.Println("Delegating query to DNS truth agent...")
+	}
+	req := dnstruth.ParseRequest(question)
+	if req.Name == "" {
+		return fmt.Errorf("name the domain to check, such as api.example.com")
+	}
+
+	var awsRunner aws.Runner
+	targetProfile := resolveAWSProfile(profile)
+	if awsClient, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug); err == nil {
+		awsRunner = awsClient.ExecCLI
+	} else if debug {
+		fmt.Printf("[dnstruth] AWS profile %s unavailable: %v\n", targetProfile, err)
+	}
+	var cfClient cfdns.CloudflareClient
+	if client, err := newCloudflareAgentClient(ctx, debug); err == nil {
+		cfClient = client
+	} else if debug {
+		fmt.Printf("[dnstruth] Cloudflare unavailable: %v\n", err)
+	}
+
+	agent := dnstruth.NewAgent(awsRunner, cfClient, net.DefaultResolver, debug)
+	report := agent.Check(ctx, req)
+	fmt.Print(report.Format())
+
+	corrections := dnstruth.BuildCorrections(report, question, time.Now())
+	if !req.Fix {
+		if len(corrections.Plans) > 0 {
+			fmt.Println("\nAsk again with \"fix\" to plan deleting the dangling records.")
+		}
+		return nil
+	}
+	if text := corrections.Format(); text != "" {
+		fmt.Printf("\n%s", text)
+	}
+	for _, plan := range corrections.Plans {
+		planJSON, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format plan: %w", err)
+		}
+		fmt.Printf("\n%s\n\n", plan.Summary)
+		fmt.Println(string(planJSON))
+		if err := runPlanGeneratedHooks("ask dns-truth", planJSON); err != nil {
+			return err
+		}
+		saveGeneratedPlan(planstore.KindMaker, plan.Provider, "ask dns-truth", question, plan.Summary, planJSON)
+		fmt.Println("\n// To apply this plan, run:")
+		fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	}
+	return nil
+}
+
+// shouldRouteToAzureInfraAgent reports whether a question asks to list
+// Azure VMs, storage accounts, VNets, Function Apps or container
+// registries, or to create one or change a VM's power state or size
+func shouldRouteToAzureInfraAgent(question string) bool {
+	if !azureinfra.IsInfraQuestion(question) {
+		return false
+	}
+	// AWS inference fires on generic words like storage and subnet, so
+	// IsInfraQuestion turns away AWS questions itself
+	svcCtx := routing.InferContext(question)
+	return !svcCtx.GCP && !svcCtx.DigitalOcean && !svcCtx.Hetzner && !svcCtx.Oracle && !svcCtx.Cloudflare
+}
+
+// handleAzureInfraQuery lists Azure VMs, storage accounts, VNets, Function
+// Apps and registries, or prints a plan to create one or to start, stop,
+// deallocate, restart or resize a VM. Plans are never applied here.
+func handleAzureInfraQuery(ctx context.Context, question string, debug bool, subscription string) error {
+	sub := strings.TrimSpace(subscription)
+	if sub == "" {
+		sub = strings.TrimSpace(azure.ResolveSubscriptionID())
+	}
+	if debug {
+		fmt.Printf("Delegating query to Azure infrastructure agent (subscription %q)...\n", sub)
+	}
+	azureClient := azure.NewClientWithOptionalSubscription(sub, debug)
+
+	agent := azureinfra.NewAgent(azureClient.ExecCLI, debug)
+	req := azureinfra.ParseRequest(question)
+	if req.Op == azureinfra.List {
+		fmt.Print(agent.Inventory(ctx, req.Services).Format())
+		return nil
+	}
+
+	defaults := azureinfra.Defaults{
+		ResourceGroup: strings.TrimSpace(viper.GetString("infra.azure.resource_group")),
+		Location:      strings.TrimSpace(viper.GetString("infra.azure.region")),
+	}
+	plan, err := agent.Plan(ctx, req, defaults, question, time.Now())
+	if err != nil {
+		return err
+	}
+	planJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", plan.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks("ask azure-infra", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, "azure", "ask azure-infra", question, plan.Summary, planJSON)
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
+
 // handleDigitalOceanQuery delegates a Digital Ocean query to the DO client
 func handleDigitalOceanQuery(ctx context.Context, question string, debug bool) error {
 	if debug {
@@ -2600,8 +3600,30 @@ Provide a clear, concise answer based on the data above. Cite compartment OCIDs,
 	return nil
 }
 
+// shouldRouteToTicketAgent reports whether a question asks to file the last
+// stored report as a ticket, e.g. "file a ticket for this"
+func shouldRouteToTicketAgent(question string) bool {
+	_, ok := ticket.RequestedTracker(question)
+	return ok
+}
+
+// shouldRouteToSentryAgent reports whether a question is about Sentry,
+// named or implied by crashers or release health, and asks for an
+// operation the Sentry agent runs or plans itself
+func shouldRouteToSentryAgent(question string) bool {
+	return triage.IsTriageQuestion(question) && triage.ParseOpRequest(question).Op != ""
+}
+
+// shouldRouteToVercelAgent reports whether a question names Vercel and asks
+// for an operation the Vercel agent runs or plans itself
+func shouldRouteToVercelAgent(question string) bool {
+	return routing.InferContext(question).Vercel && vercel.ParseOpRequest(question).Op != ""
+}
+
 // handleVercelQuery delegates a Vercel query to the Vercel agent with
-// per-team conversation history for multi-turn context.
+// per-team conversation history for multi-turn context. Listing projects,
+// deployments or env vars and reading build logs are answered directly,
+// and redeploys and env changes are printed as plans.
 func handleVercelQuery(ctx context.Context, question string, debug bool) error {
 	if debug {
 		fmt.Println("Delegating query to Vercel agent...")
@@ -2617,6 +3639,10 @@ func handleVercelQuery(ctx context.Context, question string, debug bool) error {
 		return fmt.Errorf("failed to create Vercel client: %w", err)
 	}
 
+	if req := vercel.ParseOpRequest(question); req.Op != "" {
+		return handleVercelOp(ctx, client, req, question)
+	}
+
 	// Load conversation history keyed by team (or "personal" for non-team accounts).
 	conversationID := teamID
 	if conversationID == "" {
@@ -2678,7 +3704,53 @@ func handleVercelQuery(ctx context.Context, question string, debug bool) error {
 	if err := history.Save(); err != nil && debug {
 		fmt.Fprintf(os.Stderr, "[debug] save history: %v\n", err)
 	}
-
+
+	return nil
+}
+
+// handleVercelOp runs a Vercel read, or prints the plan for a redeploy or
+// env change. Plans are never applied here.
+func handleVercelOp(ctx context.Context, client *vercel.Client, req vercel.OpRequest, question string) error {
+	ops := vercel.NewOps(client, time.Now())
+	if !req.Op.Mutates() {
+		out, err := ops.Read(ctx, req)
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+		if !strings.HasSuffix(out, "\n") {
+			fmt.Println()
+		}
+		return nil
+	}
+
+	var latest *vercel.Deployment
+	if req.Op == vercel.OpRedeploy && req.Project != "" {
+		d, err := ops.LatestDeployment(ctx, req.Project, req.Target, false)
+		if err != nil {
+			return err
+		}
+		latest = d
+	}
+	opPlan, err := vercel.BuildOpPlan(req, latest, question, time.Now())
+	if err != nil {
+		return err
+	}
+	planJSON, err := json.MarshalIndent(opPlan.Plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", opPlan.Plan.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks("ask vercel", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, opPlan.Plan.Provider, "ask vercel", question, opPlan.Plan.Summary, planJSON)
+	for _, note := range opPlan.Notes {
+		fmt.Printf("\n// %s", note)
+	}
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
 	return nil
 }
 
@@ -2737,8 +3809,16 @@ func resolveFlyioToken(ctx context.Context, debug bool) (apiToken string, orgSlu
 	return "", "", fmt.Errorf("Fly.io token not configured. Set flyio.api_token in ~/.clanker.yaml or export FLY_API_TOKEN")
 }
 
+// shouldRouteToFlyioAgent reports whether a question names Fly.io and asks
+// for an operation the Fly.io agent runs or plans itself
+func shouldRouteToFlyioAgent(question string) bool {
+	return routing.InferContext(question).Flyio && flyio.ParseOpRequest(question).Op != ""
+}
+
 // handleFlyioQuery delegates a Fly.io query to the Fly.io agent with per-org
-// conversation history for multi-turn context.
+// conversation history for multi-turn context. Listing apps, machines or
+// secrets and reading logs are answered directly, and deploys, scaling and
+// secret changes are printed as plans.
 func handleFlyioQuery(ctx context.Context, question string, debug bool) error {
 	if debug {
 		fmt.Println("Delegating query to Fly.io agent...")
@@ -2754,6 +3834,10 @@ func handleFlyioQuery(ctx context.Context, question string, debug bool) error {
 		return fmt.Errorf("failed to create Fly.io client: %w", err)
 	}
 
+	if req := flyio.ParseOpRequest(question); req.Op != "" {
+		return handleFlyioOp(ctx, client, req, question)
+	}
+
 	// Load conversation history keyed by org slug (or "personal" when unscoped).
 	conversationID := orgSlug
 	if conversationID == "" {
@@ -2818,6 +3902,43 @@ func handleFlyioQuery(ctx context.Context, question string, debug bool) error {
 	return nil
 }
 
+// handleFlyioOp runs a Fly read, or prints the plan for a deploy, scale or
+// secrets change. Plans are never applied here.
+func handleFlyioOp(ctx context.Context, client *flyio.Client, req flyio.OpRequest, question string) error {
+	if !req.Op.Mutates() {
+		out, err := flyio.NewOps(client, flyio.FlyctlInstalled()).Read(ctx, req)
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+		if !strings.HasSuffix(out, "\n") {
+			fmt.Println()
+		}
+		return nil
+	}
+
+	opPlan, err := flyio.BuildOpPlan(req, question, time.Now())
+	if err != nil {
+		return err
+	}
+	planJSON, err := json.MarshalIndent(opPlan.Plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", opPlan.Plan.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks("ask flyio", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, opPlan.Plan.Provider, "ask flyio", question, opPlan.Plan.Summary, planJSON)
+	for _, note := range opPlan.Notes {
+		fmt.Printf("\n// %s", note)
+	}
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
+
 // buildFlyioPrompt assembles the system prompt for a Fly.io ask query,
 // injecting infrastructure context and recent conversation history.
 func buildFlyioPrompt(question, flyioContext, historyContext string) string {
@@ -2868,8 +3989,16 @@ func resolveRailwayToken(ctx context.Context, debug bool) (apiToken string, work
 	return "", "", fmt.Errorf("Railway token not configured. Set railway.api_token in ~/.clanker.yaml or export RAILWAY_API_TOKEN")
 }
 
+// shouldRouteToRailwayAgent reports whether a question names Railway and
+// asks for an operation the Railway agent runs or plans itself
+func shouldRouteToRailwayAgent(question string) bool {
+	return routing.InferContext(question).Railway && railway.ParseOpRequest(question).Op != ""
+}
+
 // handleRailwayQuery delegates a Railway query to the Railway agent with
-// per-workspace conversation history for multi-turn context.
+// per-workspace conversation history for multi-turn context. Listing
+// projects, deployments or variables and reading build logs are answered
+// directly, and redeploys and variable changes are printed as plans.
 func handleRailwayQuery(ctx context.Context, question string, debug bool) error {
 	if debug {
 		fmt.Println("Delegating query to Railway agent...")
@@ -2885,6 +4014,10 @@ func handleRailwayQuery(ctx context.Context, question string, debug bool) error
 		return fmt.Errorf("failed to create Railway client: %w", err)
 	}
 
+	if req := railway.ParseOpRequest(question); req.Op != "" {
+		return handleRailwayOp(ctx, client, req, question)
+	}
+
 	conversationID := workspaceID
 	if conversationID == "" {
 		conversationID = "personal"
@@ -2947,6 +4080,48 @@ func handleRailwayQuery(ctx context.Context, question string, debug bool) error
 	return nil
 }
 
+// handleRailwayOp runs a Railway read, or prints the plan for a redeploy or
+// variables change. Plans are never applied here.
+func handleRailwayOp(ctx context.Context, client *railway.Client, req railway.OpRequest, question string) error {
+	ops := railway.NewOps(client, time.Now())
+	if !req.Op.Mutates() {
+		out, err := ops.Read(ctx, req)
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+		if !strings.HasSuffix(out, "\n") {
+			fmt.Println()
+		}
+		return nil
+	}
+
+	target, err := ops.Resolve(ctx, req)
+	if err != nil {
+		return err
+	}
+	opPlan, err := railway.BuildOpPlan(req, target, question, time.Now())
+	if err != nil {
+		return err
+	}
+	planJSON, err := json.MarshalIndent(opPlan.Plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", opPlan.Plan.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks("ask railway", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, opPlan.Plan.Provider, "ask railway", question, opPlan.Plan.Summary, planJSON)
+	for _, note := range opPlan.Notes {
+		fmt.Printf("\n// %s", note)
+	}
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
+
 // buildRailwayPrompt assembles the system prompt for a Railway ask query,
 // injecting infrastructure context and recent conversation history when
 // available.
@@ -3182,6 +4357,12 @@ func handleK8sQuery(ctx context.Context, question string, debug bool, kubeconfig
 		return handleK8sClusterProvisioning(ctx, question, questionLower, awsProfile, awsRegion, debug)
 	}
 
+	// A canary or blue/green request names the new image or version; without
+	// one it is a question about a rollout already running
+	if req, ok := rollout.ParseRequest(question); ok && (req.Image != "" || req.Version != "") {
+		return handleK8sRollout(ctx, question, req, debug)
+	}
+
 	// Check if this is a deployment request (creating a deployment, not listing)
 	// Exclude read-only queries that mention "deployment" or "deployments"
 	isReadOnlyQuery := strings.Contains(questionLower, "list") ||
@@ -3213,6 +4394,15 @@ func handleK8sQuery(ctx context.Context, question string, debug bool, kubeconfig
 		Region:     awsRegion,
 		Kubeconfig: kubeconfig,
 	})
+	if tracker := newSentryErrorTracker(debug); tracker != nil {
+		k8sAgent.SetErrorTracker(tracker)
+	}
+	// The model classifies questions the category rules are unsure of and
+	// reads namespaces and names the patterns miss, such as "pods of the
+	// checkout app in staging"; without one the rules and patterns answer
+	if aiClient, err := createAIClient(debug); err == nil {
+		k8sAgent.SetQueryModel(aiClient.AskPrompt)
+	}
 
 	// Configure query options
 	opts := k8s.QueryOptions{
@@ -3220,6 +4410,8 @@ func handleK8sQuery(ctx context.Context, question string, debug bool, kubeconfig
 		ClusterType: k8s.ClusterType(viper.GetString("kubernetes.default_type")),
 		Namespace:   viper.GetString("kubernetes.default_namespace"),
 		Kubeconfig:  kubeconfig,
+		Context:     viper.GetString("kubernetes.default_context"),
+		ListOptions: k8s.ListOptions{SummaryThreshold: viper.GetInt("kubernetes.summary_threshold")},
 	}
 
 	if opts.Namespace == "" {
@@ -3229,8 +4421,17 @@ func handleK8sQuery(ctx context.Context, question string, debug bool, kubeconfig
 		opts.ClusterType = k8s.ClusterTypeExisting
 	}
 
-	// Handle the query
+	// Handle the query, answering the agent's questions about which
+	// cluster or workload it means instead of letting it guess
 	response, err := k8sAgent.HandleQuery(ctx, question, opts)
+	for i := 0; err == nil && response.Type == k8s.ResponseTypeNeedsClarification && i < maxClarifications; i++ {
+		answer, askErr := askClarification(response.Clarification)
+		if askErr != nil || answer == "" {
+			return askErr
+		}
+		opts = opts.Clarify(response.Clarification.Field, answer)
+		response, err = k8sAgent.HandleQuery(ctx, question, opts)
+	}
 	if err != nil {
 		return fmt.Errorf("K8s agent error: %w", err)
 	}
@@ -3238,17 +4439,33 @@ func handleK8sQuery(ctx context.Context, question string, debug bool, kubeconfig
 	// Output based on response type
 	switch response.Type {
 	case k8s.ResponseTypePlan:
+		if response.Plan != nil {
+			annotateK8sPlanTools(response.Plan)
+		}
 		// Output plan as JSON (like AWS maker)
 		planJSON, err := json.MarshalIndent(response.Plan, "", "  ")
 		if err != nil {
 			return fmt.Errorf("failed to format plan: %w", err)
 		}
 		fmt.Println(string(planJSON))
+		if err := runPlanGeneratedHooks("ask k8s", planJSON); err != nil {
+			return err
+		}
+		if response.Plan != nil {
+			saveGeneratedPlan(planstore.KindK8s, "kubernetes", "ask k8s", question, response.Plan.Summary, planJSON)
+		}
 		fmt.Println("\n// To apply this plan, run:")
-		fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+		fmt.Println("// clanker plan apply <id>   (or: clanker ask --apply --plan-file <save-above-to-file.json>)")
 
 	case k8s.ResponseTypeResult:
 		fmt.Println(response.Result)
+		if response.Report != nil {
+			r := saveReport(reportstore.KindK8sDiagnosis, "ask k8s", question, response.Report.Summary, response.Result, response.Report)
+			return fileRequestedTicket(ctx, r, debug)
+		}
+
+	case k8s.ResponseTypeNeedsClarification:
+		return fmt.Errorf("still unclear after %d answers: %s", maxClarifications, response.Summary)
 
 	case k8s.ResponseTypeError:
 		return response.Error
@@ -3353,12 +4570,17 @@ func handleEKSCreation(ctx context.Context, clusterName string, nodeCount int, i
 	// Convert to maker-compatible format and output JSON (same as AWS maker)
 	question := fmt.Sprintf("create an eks cluster called %s with %d node using %s", clusterName, nodeCount, instanceType)
 	makerPlan := k8sPlan.ToMakerPlan(question)
+	annotateK8sMakerPlanTools(makerPlan)
 	planJSON, err := json.MarshalIndent(makerPlan, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to format plan: %w", err)
 	}
 	fmt.Println(string(planJSON))
 
+	if err := runPlanGeneratedHooks("ask k8s eks", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindK8s, "kubernetes", "ask k8s eks", question, makerPlan.Summary, planJSON)
 	return nil
 }
 
@@ -3386,68 +4608,113 @@ func handleKubeadmCreation(ctx context.Context, clusterName string, workerCount
 		KubernetesVersion: "1.29",
 		KeyPairName:       keyPairName,
 		SSHKeyPath:        sshKeyPath,
+		SSHUser:           kubeadmSSHUser(),
+		JumpHost:          kubeadmJumpHost(),
 		CNI:               "calico",
 	})
 
 	// Convert to maker-compatible format and output JSON (same as AWS maker)
 	question := fmt.Sprintf("create a kubeadm cluster called %s with %d workers using %s", clusterName, workerCount, instanceType)
 	makerPlan := k8sPlan.ToMakerPlan(question)
+	annotateK8sMakerPlanTools(makerPlan)
 	planJSON, err := json.MarshalIndent(makerPlan, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to format plan: %w", err)
 	}
 	fmt.Println(string(planJSON))
 
+	if err := runPlanGeneratedHooks("ask k8s kubeadm", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindK8s, "kubernetes", "ask k8s kubeadm", question, makerPlan.Summary, planJSON)
 	return nil
 }
 
+// deploySpecTimeout bounds the model call reading a deploy request
+const deploySpecTimeout = 20 * time.Second
+
 // handleK8sDeployment handles deployment requests - outputs plan JSON like AWS maker
 func handleK8sDeployment(ctx context.Context, question, questionLower string, debug bool) error {
-	// Extract image from question
-	image := extractImage(questionLower)
-	if image == "" {
-		image = "nginx"
+	// Read image, name, replicas, resources, probes, env and exposure from
+	// the question; the model reads phrasings the patterns miss, and the
+	// patterns answer alone without one
+	var ask manifestgen.AskFunc
+	if aiClient, err := createAIClient(debug); err == nil {
+		ask = aiClient.AskPrompt
+	}
+	parseCtx, cancel := context.WithTimeout(ctx, deploySpecTimeout)
+	spec, ok := manifestgen.ParseQueryWithModel(parseCtx, question, ask)
+	cancel()
+
+	// Ask for an image or port the question leaves out instead of guessing
+	if answered, err := completeDeploySpec(&spec, ok); !answered {
+		return err
 	}
-
-	// Extract deployment name
-	deployName := extractDeployName(questionLower)
-	if deployName == "" {
-		// Extract from image
-		parts := strings.Split(image, "/")
-		deployName = parts[len(parts)-1]
-		if idx := strings.Index(deployName, ":"); idx > 0 {
-			deployName = deployName[:idx]
-		}
+	if spec.Namespace == "" {
+		spec.Namespace = viper.GetString("kubernetes.default_namespace")
+	}
+	if spec.Namespace == "" {
+		spec.Namespace = "default"
+	}
+	if spec.Replicas == 0 && spec.Autoscale == nil {
+		spec.Replicas = 1
+	}
+	if spec.ServiceType == "" && spec.Ingress == nil && !spec.IsBatch() {
+		spec.ServiceType = "LoadBalancer"
 	}
 
-	// Extract port
-	port := 80
+	// A missing tag or an unpullable private image would only show up as
+	// ImagePullBackOff after apply
+	pull, err := checkDeployImage(ctx, spec.Image, spec.Namespace, debug)
+	if err != nil {
+		return err
+	}
+	spec.PullSecret = pull.SecretName
 
-	// Extract replicas
-	replicas := 1
+	manifests, err := manifestgen.Build(spec)
+	if err != nil {
+		return fmt.Errorf("invalid deployment request: %w", err)
+	}
+	if debug {
+		fmt.Printf("[k8s] generated manifests:\n%s\n", manifestgen.JoinYAML(manifests))
+	}
 
-	// Extract namespace
-	namespace := "default"
+	replicas := spec.Replicas
+	if spec.Autoscale != nil {
+		replicas = spec.Autoscale.MinReplicas
+	}
 
 	// Generate deploy plan
 	deployPlan := plan.GenerateDeployPlan(plan.DeployOptions{
-		Name:      deployName,
-		Image:     image,
-		Port:      port,
-		Replicas:  replicas,
-		Namespace: namespace,
-		Type:      "deployment",
+		Name:      spec.Name,
+		Image:     spec.Image,
+		Port:      int(spec.Port),
+		Replicas:  int(replicas),
+		Namespace: spec.Namespace,
+		Type:      strings.ToLower(spec.WorkloadKind()),
+		Schedule:  spec.Schedule,
+		Manifest:  manifestgen.JoinYAML(manifests),
+
+		PullSecret:       pull.SecretName,
+		PullSecretConfig: pull.DockerConfig,
 	})
+	deployPlan.Notes = append(deployPlan.Notes, pull.Notes...)
 
 	// Convert to maker-compatible format and output JSON (same as AWS maker)
-	deployQuestion := fmt.Sprintf("deploy %s to kubernetes", image)
+	deployQuestion := fmt.Sprintf("deploy %s to kubernetes", spec.Image)
 	makerPlan := deployPlan.ToMakerPlan(deployQuestion)
+	annotateK8sMakerPlanTools(makerPlan)
+	noteDeployImageScans(ctx, makerPlan)
 	planJSON, err := json.MarshalIndent(makerPlan, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to format plan: %w", err)
 	}
 	fmt.Println(string(planJSON))
 
+	if err := runPlanGeneratedHooks("ask k8s deploy", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindK8s, "kubernetes", "ask k8s deploy", deployQuestion, makerPlan.Summary, planJSON)
 	return nil
 }
 
@@ -3498,42 +4765,6 @@ func extractInstanceType(question string) string {
 	return ""
 }
 
-func extractImage(question string) string {
-	// Look for common image patterns
-	words := strings.Fields(question)
-	for _, word := range words {
-		// Check for docker image patterns
-		if strings.Contains(word, "/") || strings.Contains(word, ":") {
-			return strings.TrimRight(word, ".,;:!?")
-		}
-		// Check for common images
-		commonImages := []string{"nginx", "redis", "postgres", "mysql", "mongo", "node", "python", "golang"}
-		for _, img := range commonImages {
-			if word == img {
-				return img
-			}
-		}
-	}
-	return ""
-}
-
-func extractDeployName(question string) string {
-	// Look for "called X" or "named X" patterns
-	patterns := []string{"called ", "named ", "name "}
-	for _, pattern := range patterns {
-		if idx := strings.Index(question, pattern); idx != -1 {
-			rest := question[idx+len(pattern):]
-			words := strings.Fields(rest)
-			if len(words) > 0 {
-				name := words[0]
-				name = strings.TrimRight(name, ".,;:!?")
-				return name
-			}
-		}
-	}
-	return ""
-}
-
 // formatK8sCommand formats a command for display (like AWS maker formatAWSArgsForLog)
 func formatK8sCommand(cmdName string, args []string) string {
 	const maxArgLen = 160
@@ -3554,25 +4785,57 @@ func formatK8sCommand(cmdName string, args []string) string {
 	return s
 }
 
+// dryRunMakerProviders are the maker plan providers whose executors honor
+// --dry-run
+var dryRunMakerProviders = map[string]bool{
+	"aws": true, "gcp": true, "azure": true, "cloudflare": true,
+}
+
 // isK8sPlan checks if a plan JSON is a K8s plan (contains eksctl, kubectl, or kubeadm commands)
 func isK8sPlan(rawPlan string) bool {
 	return strings.Contains(rawPlan, `"eksctl"`) ||
 		strings.Contains(rawPlan, `"kubectl"`) ||
 		strings.Contains(rawPlan, `"kubeadm"`) ||
 		strings.Contains(rawPlan, `"helm_cmds"`) ||
-		strings.Contains(rawPlan, `"helm"`)
+		strings.Contains(rawPlan, `"helm"`) ||
+		strings.Contains(rawPlan, `"manifests"`)
 }
 
-// executeK8sPlan executes a K8s plan (supports both K8sPlan with helm_cmds and MakerPlan formats)
-func executeK8sPlan(ctx context.Context, rawPlan string, profile string, debug bool) error {
-	// First try to parse as K8sPlan (with helm_cmds)
+// executeK8sPlan executes a K8s plan (supports both K8sPlan with helm_cmds or manifests and MakerPlan formats)
+func executeK8sPlan(ctx context.Context, rawPlan string, profile string, identity *k8s.Impersonation, force, overridePolicy, allowVulnerable bool, owner *planOwnership, reval planRevalidation, debug bool) (runErr error) {
+	if err := owner.check(); err != nil {
+		return err
+	}
+
+	// First try to parse as K8sPlan (with kubectl_cmds, helm_cmds or manifests)
 	var k8sPlan k8s.K8sPlan
-	if err := json.Unmarshal([]byte(rawPlan), &k8sPlan); err == nil && len(k8sPlan.HelmCmds) > 0 {
+	if err := json.Unmarshal([]byte(rawPlan), &k8sPlan); err == nil && (len(k8sPlan.KubectlCmds) > 0 || len(k8sPlan.HelmCmds) > 0 || len(k8sPlan.Manifests) > 0) {
+		audit.SetPlan("kubernetes", []byte(rawPlan), k8sPlan.Summary)
+		warnIfStalePlan(k8sPlan.CreatedAt, reval.MaxAge)
+		if _, err := preflightPlanTools(ctx, k8sPlanStepTools(&k8sPlan), nil, debug); err != nil {
+			return err
+		}
+		if err := checkK8sPlanPolicy(k8s.PlanOperations(&k8sPlan, ""), k8sPlan.Summary, overridePolicy); err != nil {
+			return err
+		}
+		if err := revalidateK8sAgentPlan(ctx, &k8sPlan, reval, debug); err != nil {
+			return err
+		}
+		if err := checkK8sPlanManifests(ctx, &k8sPlan, identity, force, debug); err != nil {
+			return err
+		}
+		if err := checkPlanImages(ctx, k8s.PlanImages(&k8sPlan), k8sPlan.Summary, allowVulnerable, debug); err != nil {
+			return err
+		}
+
 		fmt.Printf("\n[k8s] Executing plan: %s\n", k8sPlan.Summary)
 		fmt.Println(strings.Repeat("-", 60))
 
+		impersonated := newImpersonatedApply(identity, k8sPlan.Summary, debug)
+		defer func() { impersonated.finish(runErr) }()
+
 		// Execute helm commands
-		totalSteps := len(k8sPlan.HelmCmds) + len(k8sPlan.KubectlCmds)
+		totalSteps := len(k8sPlan.HelmCmds) + len(k8sPlan.KubectlCmds) + len(k8sPlan.Manifests)
 		stepNum := 0
 
 		for _, helmCmd := range k8sPlan.HelmCmds {
@@ -3584,11 +4847,29 @@ func executeK8sPlan(ctx context.Context, rawPlan string, profile string, debug b
 
 			fmt.Printf("[k8s] running %d/%d: helm %s\n", stepNum, totalSteps, strings.Join(args, " "))
 
-			cmd := exec.CommandContext(ctx, "helm", args...)
+			args, err := impersonated.stepArgs(ctx, stepNum, "helm", args)
+			if err != nil {
+				return err
+			}
+			if dryrun.Skip(os.Stdout, "helm", args) {
+				continue
+			}
+			// helm enforces the step's own timeout through --timeout
+			stepCtx, done, err := planrun.Step(ctx, "")
+			if err != nil {
+				return err
+			}
+			cmd := exec.CommandContext(stepCtx, "helm", args...)
 			cmd.Stdout = os.Stdout
 			cmd.Stderr = os.Stderr
 
-			if err := cmd.Run(); err != nil {
+			err = done(cmd.Run())
+			impersonated.stepDone(stepNum, "helm", args, err)
+			audit.RecordStep("helm", args, err)
+			if err != nil && planrun.Interrupted(ctx) {
+				return planrun.Interrupt(stepNum-1, totalSteps)
+			}
+			if err != nil {
 				return fmt.Errorf("helm command failed: %w", err)
 			}
 			fmt.Println()
@@ -3598,18 +4879,83 @@ func executeK8sPlan(ctx context.Context, rawPlan string, profile string, debug b
 		for _, kubectlCmd := range k8sPlan.KubectlCmds {
 			stepNum++
 			fmt.Printf("[k8s] running %d/%d: kubectl %s\n", stepNum, totalSteps, strings.Join(kubectlCmd.Args, " "))
+			// These steps have no stdin; one reading files stops pruning
+			owner.kubectlStdin(kubectlCmd.Args, "")
 
-			cmd := exec.CommandContext(ctx, "kubectl", kubectlCmd.Args...)
+			args, err := impersonated.stepArgs(ctx, stepNum, "kubectl", kubectlCmd.Args)
+			if err != nil {
+				return err
+			}
+			if dryrun.Skip(os.Stdout, "kubectl", args) {
+				continue
+			}
+			stepCtx, done, err := planrun.Step(ctx, kubectlCmd.Timeout)
+			if err != nil {
+				return fmt.Errorf("step %d: %w", stepNum, err)
+			}
+			cmd := exec.CommandContext(stepCtx, "kubectl", args...)
 			cmd.Stdout = os.Stdout
 			cmd.Stderr = os.Stderr
 
-			if err := cmd.Run(); err != nil {
+			err = done(cmd.Run())
+			impersonated.stepDone(stepNum, "kubectl", args, err)
+			audit.RecordStep("kubectl", args, err)
+			if err != nil && planrun.Interrupted(ctx) {
+				return planrun.Interrupt(stepNum-1, totalSteps)
+			}
+			if err != nil {
 				return fmt.Errorf("kubectl command failed: %w", err)
 			}
 			fmt.Println()
 		}
 
+		// Apply manifests
+		for _, manifest := range k8sPlan.Manifests {
+			stepNum++
+			fmt.Printf("[k8s] running %d/%d: kubectl apply %s/%s\n", stepNum, totalSteps, manifest.Kind, manifest.Name)
+
+			args := []string{"apply", "-f", "-"}
+			if manifest.Namespace != "" {
+				args = append(args, "-n", manifest.Namespace)
+			}
+			content := owner.kubectlStdin(args, manifest.Content)
+			args, err := impersonated.stepArgs(ctx, stepNum, "kubectl", args)
+			if err != nil {
+				return err
+			}
+			if dryrun.Enabled() {
+				dryrun.PrintInput(os.Stdout, "kubectl", args, manifest.Kind+"/"+manifest.Name)
+				continue
+			}
+			stepCtx, done, err := planrun.Step(ctx, manifest.Timeout)
+			if err != nil {
+				return fmt.Errorf("step %d: %w", stepNum, err)
+			}
+			cmd := exec.CommandContext(stepCtx, "kubectl", args...)
+			cmd.Stdin = strings.NewReader(content)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+
+			err = done(cmd.Run())
+			impersonated.stepDone(stepNum, "kubectl", args, err)
+			audit.RecordStep("kubectl", args, err)
+			if err != nil && planrun.Interrupted(ctx) {
+				return planrun.Interrupt(stepNum-1, totalSteps)
+			}
+			if err != nil {
+				return fmt.Errorf("manifest apply failed for %s/%s: %w", manifest.Kind, manifest.Name, err)
+			}
+			fmt.Println()
+		}
+		if err := owner.pruneOrphans(ctx, impersonated, totalSteps+1, debug); err != nil {
+			return err
+		}
+
 		fmt.Println(strings.Repeat("-", 60))
+		if dryrun.Enabled() {
+			fmt.Println("[k8s] Dry run finished; nothing was changed.")
+			return nil
+		}
 		fmt.Println("[k8s] Plan executed successfully!")
 		return nil
 	}
@@ -3620,6 +4966,9 @@ func executeK8sPlan(ctx context.Context, rawPlan string, profile string, debug b
 		return fmt.Errorf("failed to parse K8s plan: %w", err)
 	}
 
+	audit.SetPlan("kubernetes", []byte(rawPlan), makerPlan.Summary)
+	warnIfStalePlan(makerPlan.CreatedAt, reval.MaxAge)
+
 	// Resolve AWS profile
 	awsProfile := resolveAWSProfile(profile)
 
@@ -3632,11 +4981,35 @@ func executeK8sPlan(ctx context.Context, rawPlan string, profile string, debug b
 		awsRegion = "us-east-1"
 	}
 
+	if err := checkK8sPlanPolicy(makerPlanOperations(makerPlan.Commands), makerPlan.Summary, overridePolicy); err != nil {
+		return err
+	}
+	if err := checkPlanImages(ctx, imagescan.ManifestImages(makerPlanManifests(makerPlan.Commands)...), makerPlan.Summary, allowVulnerable, debug); err != nil {
+		return err
+	}
+
+	// Check every CLI up front rather than failing half way through
+	steps := k8sMakerPlanSteps(&makerPlan)
+	substitutions, err := preflightPlanTools(ctx, stepTools(steps, ""), steps, debug)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("\n[k8s] Executing plan: %s\n", makerPlan.Summary)
 	fmt.Println(strings.Repeat("-", 60))
 
+	impersonated := newImpersonatedApply(identity, makerPlan.Summary, debug)
+	defer func() { impersonated.finish(runErr) }()
+
 	// Execute each command
 	for i, cmd := range makerPlan.Commands {
+		if alt, ok := substitutions[i+1]; ok {
+			// Keep the region the eksctl step would have been given
+			if !slices.Contains(alt, "--region") {
+				alt = append(alt, "--region", awsRegion)
+			}
+			cmd.Args = alt
+		}
 		if len(cmd.Args) == 0 {
 			continue
 		}
@@ -3662,23 +5035,90 @@ func executeK8sPlan(ctx context.Context, rawPlan string, profile string, debug b
 		displayCmd := formatK8sCommand(cmdName, cmdArgs)
 		fmt.Printf("[k8s] running %d/%d: %s\n", i+1, len(makerPlan.Commands), displayCmd)
 
+		if cmdName == "kubectl" {
+			cmd.Stdin = owner.kubectlStdin(cmdArgs, cmd.Stdin)
+		}
+
+		// Run kubectl/helm as the impersonated identity, if any
+		cmdArgs, err := impersonated.stepArgs(ctx, i+1, cmdName, cmdArgs)
+		if err != nil {
+			return err
+		}
+		if dryrun.Skip(os.Stdout, cmdName, cmdArgs) {
+			continue
+		}
+
 		// Execute the command
-		execCmd := exec.CommandContext(ctx, cmdName, cmdArgs...)
+		stepCtx, done, err := planrun.Step(ctx, cmd.Timeout)
+		if err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+		execCmd := exec.CommandContext(stepCtx, cmdName, cmdArgs...)
 		execCmd.Stdout = os.Stdout
 		execCmd.Stderr = os.Stderr
+		if cmd.Stdin != "" {
+			execCmd.Stdin = strings.NewReader(cmd.Stdin)
+		}
 
-		if err := execCmd.Run(); err != nil {
+		err = done(execCmd.Run())
+		impersonated.stepDone(i+1, cmdName, cmdArgs, err)
+		audit.RecordStep(cmdName, cmdArgs, err)
+		if err != nil && planrun.Interrupted(ctx) {
+			return planrun.Interrupt(i, len(makerPlan.Commands))
+		}
+		if err != nil {
 			return fmt.Errorf("command failed: %s: %w", cmdName, err)
 		}
 
 		fmt.Println()
 	}
+	if err := owner.pruneOrphans(ctx, impersonated, len(makerPlan.Commands)+1, debug); err != nil {
+		return err
+	}
 
 	fmt.Println(strings.Repeat("-", 60))
+	if dryrun.Enabled() {
+		fmt.Println("[k8s] Dry run finished; nothing was changed.")
+		return nil
+	}
 	fmt.Println("[k8s] Plan executed successfully!")
 	return nil
 }
 
+// checkK8sPlanManifests validates a plan's manifests against the built-in
+// API types and with a server-side dry-run, as the impersonated identity when
+// there is one. A failed check stops the apply unless force is set.
+func checkK8sPlanManifests(ctx context.Context, p *k8s.K8sPlan, identity *k8s.Impersonation, force, debug bool) error {
+	if len(p.Manifests) == 0 {
+		return nil
+	}
+
+	client := k8s.NewClient("", "", debug)
+	if identity != nil {
+		client.SetImpersonation(identity)
+	}
+	fmt.Printf("[k8s] validating %d manifests (schema and server-side dry-run)...\n", len(p.Manifests))
+	checks := k8s.CheckPlanManifests(ctx, client, p, true)
+	for _, check := range checks {
+		if check.Skipped != "" {
+			fmt.Printf("[k8s] %s/%s: dry-run skipped (%s)\n", check.Kind, check.Name, check.Skipped)
+		}
+	}
+
+	failed := k8s.FailedChecks(checks)
+	if len(failed) == 0 {
+		return nil
+	}
+	for _, check := range failed {
+		fmt.Fprintf(os.Stderr, "[k8s] validation failed: %s\n", check)
+	}
+	if !force {
+		return fmt.Errorf("%d of %d manifests failed validation; fix the plan or re-run with --force", len(failed), len(p.Manifests))
+	}
+	fmt.Fprintln(os.Stderr, "[k8s] --force given, applying anyway")
+	return nil
+}
+
 // determineRoutingDecision analyzes a question and returns which agent should handle it.
 // This is used by the --route-only flag to return routing decisions without executing.
 func determineRoutingDecision(question string) (agent string, reason string) {
@@ -3692,9 +5132,14 @@ func determineRoutingDecisionWithContext(question string, dbConnection string) (
 }
 
 type routingDecisionDetails struct {
-	Agent        string
-	Reason       string
-	DatabaseMode string
+	Agent        string              `json:"agent"`
+	Reason       string              `json:"reason"`
+	DatabaseMode string              `json:"databaseMode,omitempty"`
+	Confidence   float64             `json:"confidence"`
+	Pinned       bool                `json:"pinned,omitempty"`
+	FanOut       []string            `json:"fanOut,omitempty"`
+	Services     []string            `json:"services,omitempty"`
+	Candidates   []routing.Candidate `json:"candidates,omitempty"`
 }
 
 func determineDatabaseRouteMode(questionLower string) string {
@@ -3704,165 +5149,247 @@ func determineDatabaseRouteMode(questionLower string) string {
 	return "query"
 }
 
-func determineRoutingDecisionDetailsWithContext(question string, dbConnection string) routingDecisionDetails {
-	question = routeOnlyUserQuestion(question)
-	questionLower := strings.ToLower(question)
-	if isClankerCloudQuestion(questionLower) {
-		return routingDecisionDetails{Agent: "clanker-cloud", Reason: "Explicit Clanker Cloud app request detected"}
-	}
-
-	// Check for explicit Hermes agent requests
-	hermesKeywords := []string{"hermes", "hermes agent", "talk to hermes", "use hermes"}
-	for _, kw := range hermesKeywords {
-		if strings.Contains(questionLower, kw) {
-			return routingDecisionDetails{Agent: "hermes", Reason: "Hermes agent explicitly requested"}
-		}
-	}
-
-	// Check for IAM-specific queries first
-	iamKeywords := []string{
-		"iam role", "iam roles", "iam policy", "iam policies",
-		"iam user", "iam users", "iam permission", "iam permissions",
-		"trust policy", "assume role", "attached policies",
-		"inline policies", "permission boundary",
-		"access key", "access keys", "credential report",
-		"least privilege", "security audit", "iam analysis",
-		"overpermissive", "admin access", "cross-account trust",
-		"mfa status", "unused role", "wildcard permission",
-		"analyze iam", "fix iam", "iam security",
-	}
-	for _, kw := range iamKeywords {
-		if strings.Contains(questionLower, kw) {
-			return routingDecisionDetails{Agent: "iam", Reason: "IAM query or security analysis request"}
-		}
-	}
-
-	if shouldRouteToObservabilityAgent(questionLower) {
-		return routingDecisionDetails{Agent: "agent-observability", Reason: "Observability request detected"}
-	}
-
-	terraformSignals := []string{
-		"terraform", "tf ", "tfstate", "tf plan", "tf apply", "tf destroy",
-		"hcl", "module", "provider", "workspace", "state", "plan", "apply", "destroy",
-		"drift", "refresh", "init",
-	}
-	for _, kw := range terraformSignals {
-		if strings.Contains(questionLower, kw) {
-			return routingDecisionDetails{Agent: "terraform", Reason: "Terraform query or analysis request"}
-		}
-	}
-
-	// Check for diagram/visualization requests
-	diagramKeywords := []string{
-		"diagram", "visual", "visualize", "layout", "arrange",
-		"draw", "illustrate", "show on diagram", "add to diagram",
-		"update diagram", "modify diagram",
-	}
-	for _, kw := range diagramKeywords {
-		if strings.Contains(questionLower, kw) {
-			return routingDecisionDetails{Agent: "diagram", Reason: "Diagram or visualization request detected"}
-		}
-	}
-
-	// Action keywords for infrastructure provisioning
-	actionKeywords := []string{
-		"create", "provision", "deploy", "launch", "spin up", "set up", "setup",
-		"add", "make", "build", "install", "configure", "enable", "start",
-		"update", "modify", "change", "scale", "resize", "upgrade",
-		"delete", "remove", "destroy", "terminate", "tear down", "teardown",
-	}
-
-	// K8s resources (checked first as more specific).
-	// NOTE: dropped "postgres", "mysql", "redis", "mongodb" from this list.
-	// They are database engines that happen to be deployable on K8s, but
-	// also exist as managed services (AWS RDS / ElastiCache, GCP Cloud SQL,
-	// Supabase, etc.) and as in-process libraries. Including them here was
-	// causing "create a postgres table" / "spin up a redis cluster" to
-	// route to `k8s-maker` ahead of `maker` or `agent-database` — wrong in
-	// the common case. With these removed, those queries fall through to
-	// the AWS-resources check (maker) or the database-routing check below.
-	// "nginx" stays — it's overwhelmingly used as a K8s workload today;
-	// people running nginx on a VM tend to say "an nginx server" or
-	// "configure nginx" which still works via the cli fallback.
-	k8sResources := []string{
-		"kubernetes", "k8s", "pod", "pods", "deployment", "deployments",
-		"service", "services", "ingress", "namespace", "configmap",
-		"secret", "pvc", "persistent volume", "statefulset", "daemonset",
-		"replicaset", "cronjob", "job", "container", "helm", "chart",
-		"kubectl", "eksctl", "kubeadm", "nginx",
-		"cluster", "node", "nodes", "kube",
-	}
+// Action keywords for infrastructure provisioning
+var routingActionKeywords = []string{
+	"create", "provision", "deploy", "launch", "spin up", "set up", "setup",
+	"add", "make", "build", "install", "configure", "enable", "start",
+	"update", "modify", "change", "scale", "resize", "upgrade",
+	"delete", "remove", "destroy", "terminate", "tear down", "teardown",
+}
 
-	// AWS resources (excluding EKS which is handled by K8s maker)
-	awsResources := []string{
-		"ec2", "instance", "lambda", "function", "s3", "bucket",
-		"rds", "database", "dynamodb", "table", "sqs", "queue",
-		"sns", "topic", "ecs", "fargate", "elasticache", "memcached",
-		"elb", "alb", "nlb", "load balancer", "api gateway", "cloudfront", "cdn",
-		"route53", "dns", "iam", "role", "policy", "user",
-		"vpc", "subnet", "security group", "nat", "igw",
-		"kinesis", "stream", "glue", "athena", "redshift",
-		"elastic beanstalk", "codepipeline", "codebuild",
-	}
+// K8s resources (checked first as more specific).
+// NOTE: dropped "postgres", "mysql", "redis", "mongodb" from this list.
+// They are database engines that happen to be deployable on K8s, but
+// also exist as managed services (AWS RDS / ElastiCache, GCP Cloud SQL,
+// Supabase, etc.) and as in-process libraries. Including them here was
+// causing "create a postgres table" / "spin up a redis cluster" to
+// route to `k8s-maker` ahead of `maker` or `agent-database` — wrong in
+// the common case. With these removed, those queries fall through to
+// the AWS-resources check (maker) or the database-routing check below.
+// "nginx" stays — it's overwhelmingly used as a K8s workload today;
+// people running nginx on a VM tend to say "an nginx server" or
+// "configure nginx" which still works via the cli fallback.
+var routingK8sResources = []string{
+	"kubernetes", "k8s", "pod", "pods", "deployment", "deployments",
+	"service", "services", "ingress", "namespace", "configmap",
+	"secret", "pvc", "persistent volume", "statefulset", "daemonset",
+	"replicaset", "cronjob", "job", "container", "helm", "chart",
+	"kubectl", "eksctl", "kubeadm", "nginx",
+	"cluster", "node", "nodes", "kube",
+}
 
-	hasAction := false
-	for _, action := range actionKeywords {
-		if strings.Contains(questionLower, action) {
-			hasAction = true
-			break
-		}
-	}
+// AWS resources (excluding EKS which is handled by K8s maker)
+var routingAWSResources = []string{
+	"ec2", "instance", "lambda", "function", "s3", "bucket",
+	"rds", "database", "dynamodb", "table", "sqs", "queue",
+	"sns", "topic", "ecs", "fargate", "elasticache", "memcached",
+	"elb", "alb", "nlb", "load balancer", "api gateway", "cloudfront", "cdn",
+	"route53", "dns", "iam", "role", "policy", "user",
+	"vpc", "subnet", "security group", "nat", "igw",
+	"kinesis", "stream", "glue", "athena", "redshift",
+	"elastic beanstalk", "codepipeline", "codebuild",
+}
 
-	// Check if question mentions K8s resources
-	hasK8sResource := false
-	for _, resource := range k8sResources {
-		if strings.Contains(questionLower, resource) {
-			hasK8sResource = true
-			break
+// routingRules scores the agents ask can route to. Weights keep the order
+// the router has always checked them in, so the top candidate only changes
+// when a higher-priority signal is present.
+func routingRules(dbConnection string) []routing.Rule {
+	signal := func(pred func(string) bool, evidence string) func(string) []string {
+		return func(questionLower string) []string {
+			if pred(questionLower) {
+				return []string{evidence}
+			}
+			return nil
 		}
 	}
-
-	// CICD intent (github actions / workflow / pipeline / runner / cloud
-	// build / etc.) takes precedence over the hasAction+resource check
-	// below, even when "deployment" is in the question. Otherwise queries
-	// like "setup github actions for deployment" route to k8s-maker
-	// (because "deployment" overlaps with the K8s resources list and
-	// "setup" is an action verb) — wrong, because "github actions"
-	// unambiguously signals CI/CD. shouldRouteToCICDAgent already guards
-	// against database-context overlap (sql/schema/migration), so this
-	// early-out is safe.
-	if shouldRouteToCICDAgent(questionLower) {
-		return routingDecisionDetails{Agent: "agent-cicd", Reason: "CI/CD agent request detected"}
-	}
-
-	if hasAction {
-		// Check K8s resources first (more specific)
-		if hasK8sResource {
-			return routingDecisionDetails{Agent: "k8s-maker", Reason: "K8s infrastructure provisioning or modification request"}
-		}
-		// Check AWS resources
-		for _, resource := range awsResources {
-			if strings.Contains(questionLower, resource) {
-				return routingDecisionDetails{Agent: "maker", Reason: "AWS infrastructure provisioning or modification request"}
+	// withAction only matches provisioning verbs aimed at a resource
+	withAction := func(resources []string) func(string) []string {
+		matchAction, matchResource := routing.Keywords(routingActionKeywords...), routing.Keywords(resources...)
+		return func(questionLower string) []string {
+			actions := matchAction(questionLower)
+			if len(actions) == 0 {
+				return nil
 			}
-		}
+			found := matchResource(questionLower)
+			if len(found) == 0 {
+				return nil
+			}
+			return append(actions, found...)
+		}
+	}
+
+	return []routing.Rule{
+		{Agent: "clanker-cloud", Weight: 100, Reason: "Explicit Clanker Cloud app request detected",
+			Match: signal(isClankerCloudQuestion, "clanker cloud app")},
+		{Agent: "hermes", Weight: 95, Reason: "Hermes agent explicitly requested",
+			Match: routing.Keywords("hermes", "hermes agent", "talk to hermes", "use hermes")},
+		{Agent: "ticket", Weight: 93, Reason: "File the last diagnosis or audit report as a Linear or Notion ticket",
+			Match: signal(shouldRouteToTicketAgent, "file ticket intent")},
+		{Agent: "diagram", Weight: 92, Reason: "Draw, update or export an architecture diagram",
+			Match: signal(shouldRouteToDiagramAgent, "diagram intent")},
+		{Agent: "aws-audit", Weight: 92, Reason: "AWS security posture audit",
+			Match: signal(shouldRouteToAWSAuditAgent, "security audit intent")},
+		{Agent: "iam", Weight: 90, Reason: "IAM query or security analysis request", FanOut: true,
+			Match: routing.Keywords(
+				"iam role", "iam roles", "iam policy", "iam policies",
+				"iam user", "iam users", "iam permission", "iam permissions",
+				"trust policy", "assume role", "attached policies",
+				"inline policies", "permission boundary",
+				"access key", "access keys", "credential report",
+				"least privilege", "security audit", "iam analysis",
+				"overpermissive", "admin access", "cross-account trust",
+				"mfa status", "unused role", "wildcard permission",
+				"analyze iam", "fix iam", "iam security",
+				"access analyzer", "external access", "external exposure",
+			)},
+		{Agent: "iam", Weight: 90, Reason: "IAM role, policy, user or access key change",
+			Match: signal(shouldRouteToIAMMutation, "iam change intent")},
+		{Agent: "secrets", Weight: 89, Reason: "Secret listing, rotation, creation or reference check",
+			Match: signal(shouldRouteToSecretsAgent, "secrets intent")},
+		{Agent: "certs", Weight: 89, Reason: "Certificate inventory or expiry check across ACM, Cloudflare, GCP and cert-manager",
+			Match: signal(shouldRouteToCertsAgent, "certificate expiry intent")},
+		{Agent: "dns-truth", Weight: 89, Reason: "Where a name resolves and whether Route53 or Cloudflare serves it",
+			Match: signal(shouldRouteToDNSTruthAgent, "dns resolution or ownership intent")},
+		{Agent: "flyio", Weight: 89, Reason: "Fly.io app, machine, log, deploy, scale or secrets operation",
+			Match: signal(shouldRouteToFlyioAgent, "fly operation intent")},
+		{Agent: "vercel", Weight: 89, Reason: "Vercel project, deployment, build log, redeploy or env operation",
+			Match: signal(shouldRouteToVercelAgent, "vercel operation intent")},
+		{Agent: "railway", Weight: 89, Reason: "Railway project, deployment, build log, redeploy or variables operation",
+			Match: signal(shouldRouteToRailwayAgent, "railway operation intent")},
+		{Agent: "sentry", Weight: 89, Reason: "Sentry top crashers, issue search, release health, or resolve, ignore or assign",
+			Match: signal(shouldRouteToSentryAgent, "sentry triage intent")},
+		{Agent: "aws-rds", Weight: 89, Reason: "RDS or Aurora listing, maintenance, snapshot, restore or resize",
+			Match: signal(shouldRouteToAWSRDSAgent, "rds operation intent")},
+		{Agent: "aws-cost", Weight: 87, Reason: "AWS spend question for Cost Explorer",
+			Match: signal(shouldRouteToAWSCostAgent, "spend intent")},
+		{Agent: "aws-lambda", Weight: 87, Reason: "Lambda listing, errors, environment change or deploy",
+			Match: signal(shouldRouteToAWSLambdaAgent, "lambda operation intent")},
+		{Agent: "azure-infra", Weight: 87, Reason: "Azure VM, storage, VNet, Function App or registry listing, create, power or resize",
+			Match: signal(shouldRouteToAzureInfraAgent, "azure infrastructure intent")},
+		{Agent: "aws-ssm", Weight: 88, Reason: "Connect to or run a command on EC2 instances through SSM",
+			Match: signal(shouldRouteToAWSSSMAgent, "instance session or command intent")},
+		{Agent: "github-actions", Weight: 86, Reason: "Trigger a workflow, or re-run, cancel or approve a GitHub Actions run",
+			Match: signal(shouldRouteToGitHubActionsAgent, "workflow run change intent")},
+		{Agent: "pr-review", Weight: 86, Reason: "Summarize a numbered pull request and flag its risky changes",
+			Match: signal(shouldRouteToPRReviewAgent, "pull request review intent")},
+		{Agent: "gitlab-ci", Weight: 86, Reason: "Retry a failed GitLab pipeline or job",
+			Match: signal(shouldRouteToGitLabCIAgent, "gitlab pipeline retry intent")},
+		{Agent: "aws-logs", Weight: 86, Reason: "AWS logs question for a named log group or Lambda function",
+			Match: signal(shouldRouteToAWSLogsAgent, "logs intent with a named source")},
+		{Agent: "agent-observability", Weight: 85, Reason: "Observability request detected", FanOut: true,
+			Match: signal(shouldRouteToObservabilityAgent, "observability intent")},
+		{Agent: "terraform-state", Weight: 82, Reason: "Terraform state inspection or drift check",
+			Match: signal(shouldRouteToTerraformStateAgent, "terraform state or drift intent")},
+		{Agent: "terraform", Weight: 80, Reason: "Terraform query or analysis request",
+			Match: routing.Keywords(
+				"terraform", "tf ", "tfstate", "tf plan", "tf apply", "tf destroy",
+				"hcl", "module", "provider", "workspace", "state", "plan", "apply", "destroy",
+				"drift", "refresh", "init",
+			)},
+		{Agent: "pulumi", Weight: 80, Reason: "Pulumi stack, preview, or output question",
+			Match: routing.Keywords("pulumi")},
+		{Agent: "diagram", Weight: 75, Reason: "Diagram or visualization request detected",
+			Match: routing.Keywords(
+				"diagram", "visual", "visualize", "layout", "arrange",
+				"draw", "illustrate", "show on diagram", "add to diagram",
+				"update diagram", "modify diagram",
+			)},
+		// CICD intent (github actions / workflow / pipeline / runner / cloud
+		// build / etc.) outranks the provisioning rules below, even when
+		// "deployment" is in the question. Otherwise queries like "setup
+		// github actions for deployment" route to k8s-maker (because
+		// "deployment" overlaps with the K8s resources list and "setup" is
+		// an action verb) — wrong, because "github actions" unambiguously
+		// signals CI/CD. shouldRouteToCICDAgent already guards against
+		// database-context overlap (sql/schema/migration), so this is safe.
+		{Agent: "agent-cicd", Weight: 70, Reason: "CI/CD agent request detected", FanOut: true,
+			Match: signal(shouldRouteToCICDAgent, "ci/cd intent")},
+		{Agent: "k8s-maker", Weight: 65, Reason: "K8s infrastructure provisioning or modification request",
+			Match: withAction(routingK8sResources)},
+		{Agent: "maker", Weight: 60, Reason: "AWS infrastructure provisioning or modification request",
+			Match: withAction(routingAWSResources)},
+		{Agent: "agent-database", Weight: 55, Reason: "Database agent request detected", FanOut: true,
+			Match: signal(func(questionLower string) bool {
+				return shouldRouteToDatabaseAgentWithContext(questionLower, dbConnection)
+			}, "database intent")},
+		// K8s read queries (no action keyword but mentions K8s resources)
+		{Agent: "k8s", Weight: 50, Reason: "K8s query or analysis request", FanOut: true,
+			Match: routing.Keywords(routingK8sResources...)},
 	}
+}
 
-	if shouldRouteToDatabaseAgentWithContext(questionLower, dbConnection) {
-		return routingDecisionDetails{Agent: "agent-database", Reason: "Database agent request detected", DatabaseMode: determineDatabaseRouteMode(questionLower)}
-	}
+// pinnableAgents are the values routing.force accepts, with their aliases
+var pinnableAgents = map[string]string{
+	"k8s": "k8s", "kubernetes": "k8s",
+	"iam":      "iam",
+	"aws-cost": "aws-cost", "cost": "aws-cost",
+	"aws-audit": "aws-audit", "audit": "aws-audit",
+	"aws-logs": "aws-logs", "logs": "aws-logs",
+	"aws-ssm": "aws-ssm", "ssm": "aws-ssm",
+	"aws-rds": "aws-rds", "rds": "aws-rds",
+	"aws-lambda": "aws-lambda", "lambda": "aws-lambda",
+	"azure-infra": "azure-infra", "azure-vm": "azure-infra",
+	"secrets": "secrets", "secret": "secrets",
+	"certs": "certs", "certificates": "certs",
+	"dns-truth": "dns-truth", "dnstruth": "dns-truth",
+	"sentry":         "sentry",
+	"ticket":         "ticket",
+	"diagram":        "diagram",
+	"agent-database": "agent-database", "database": "agent-database", "db": "agent-database",
+	"agent-cicd": "agent-cicd", "cicd": "agent-cicd",
+	"github-actions": "github-actions", "gha": "github-actions",
+	"pr-review": "pr-review", "review": "pr-review",
+	"gitlab-ci":       "gitlab-ci",
+	"terraform-state": "terraform-state", "drift": "terraform-state",
+	"agent-observability": "agent-observability", "observability": "agent-observability",
+	"hermes":     "hermes",
+	"cloudflare": "cloudflare", "cf": "cloudflare",
+	"digitalocean": "digitalocean", "hetzner": "hetzner", "oracle": "oracle",
+	"vercel": "vercel", "flyio": "flyio", "railway": "railway", "verda": "verda", "tencent": "tencent",
+	"aws": "aws", "gcp": "gcp", "azure": "azure", "github": "github", "terraform": "terraform",
+	"pulumi": "pulumi", "cli": "cli",
+}
 
-	if shouldRouteToCICDAgent(questionLower) {
-		return routingDecisionDetails{Agent: "agent-cicd", Reason: "CI/CD agent request detected"}
+// pinnedAgent returns the agent routing.force pins every question to, or ""
+func pinnedAgent() string {
+	forced := routing.ForcedAgent()
+	if forced == "" {
+		return ""
 	}
-
-	// K8s read queries (no action keyword but mentions K8s resources)
-	if hasK8sResource {
-		return routingDecisionDetails{Agent: "k8s", Reason: "K8s query or analysis request"}
+	agent, ok := resolveAgentName(forced)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "[routing] warning: ignoring unknown routing.force agent %q\n", forced)
+		return ""
 	}
+	return agent
+}
 
-	// Default to CLI for general queries
-	return routingDecisionDetails{Agent: "cli", Reason: "General infrastructure query or analysis"}
+func determineRoutingDecisionDetailsWithContext(question string, dbConnection string) routingDecisionDetails {
+	question = routeOnlyUserQuestion(question)
+	rules := append(routingRules(dbConnection), pluginRoutingRules()...)
+	decision := routing.Score(question, rules, "cli", "General infrastructure query or analysis")
+	if agent := pinnedAgent(); agent != "" {
+		decision.Pin(agent, "Pinned by routing.force in the config")
+	} else if correction, ok := routing.CorrectionFor(question); ok {
+		decision.Pin(correction.Agent, "Corrected earlier with clanker route correct")
+	}
+
+	details := routingDecisionDetails{
+		Agent:      decision.Agent,
+		Reason:     decision.Reason,
+		Confidence: decision.Confidence,
+		Pinned:     decision.Pinned,
+		FanOut:     decision.FanOut,
+		Candidates: decision.Candidates,
+	}
+	if details.Agent == "agent-database" {
+		details.DatabaseMode = determineDatabaseRouteMode(strings.ToLower(question))
+	}
+	if details.Agent == "cli" {
+		// The CLI agent fans out across every provider whose context applies
+		details.Services = routing.InferContext(question).Services()
+	}
+	return details
 }
 
 func routeOnlyUserQuestion(question string) string {
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/contextcache"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+)
+
+// Default per-provider budgets for context gathering. AWS gets the most
+// room since its listings paginate across several services, and more again
+// with --all-accounts, which repeats them in every organization account.
+// GitHub likewise gets more with github.repos, which queries each
+// repository in turn.
+const (
+	awsContextTimeout         = 2 * time.Minute
+	awsOrgContextTimeout      = 5 * time.Minute
+	cloudContextTimeout       = 90 * time.Second
+	terraformContextTimeout   = 60 * time.Second
+	pulumiContextTimeout      = 3 * time.Minute
+	githubContextTimeout      = 30 * time.Second
+	githubReposContextTimeout = 3 * time.Minute
+	dbContextTimeout          = 30 * time.Second
+)
+
+// contextFetch is one provider's context lookup run by gatherContexts
+type contextFetch struct {
+	name     string
+	timeout  time.Duration
+	required bool // a failure aborts the ask instead of being reported
+	fetch    func(ctx context.Context) (string, error)
+	// cache, when set, reuses a recent result from ~/.clanker/cache
+	cache *contextCacheKey
+}
+
+// contextCacheKey names the cached inventory a fetch reads and refreshes
+type contextCacheKey struct {
+	provider string
+	profile  string
+	question string
+}
+
+// contextResult is the outcome of a single contextFetch
+type contextResult struct {
+	name    string
+	content string
+	err     error
+	elapsed time.Duration
+	cached  bool
+}
+
+// gatherContexts runs every fetch concurrently, each under its own timeout.
+// Results come back in the order the fetches were given. A fetch that
+// ignores cancellation is abandoned once its deadline passes.
+func gatherContexts(ctx context.Context, fetches []contextFetch, debug bool) []contextResult {
+	results := make([]contextResult, len(fetches))
+	// Failures are carried in results rather than returned, so one provider
+	// failing never cancels the others
+	var g errgroup.Group
+	for i, f := range fetches {
+		g.Go(func() error {
+			results[i] = runContextFetch(ctx, f)
+			if debug {
+				status := "ok"
+				if results[i].err != nil {
+					status = results[i].err.Error()
+				} else if results[i].cached {
+					status = "cached"
+				}
+				fmt.Printf("[context] %s gathered in %s (%s)\n", f.name, results[i].elapsed.Round(time.Millisecond), status)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return results
+}
+
+// contextTimeout returns ask.context_timeouts.<provider> when set, so a slow
+// account can be given more room, or fallback
+func contextTimeout(provider string, fallback time.Duration) time.Duration {
+	if d := viper.GetDuration("ask.context_timeouts." + provider); d > 0 {
+		return d
+	}
+	return fallback
+}
+
+func runContextFetch(ctx context.Context, f contextFetch) contextResult {
+	start := time.Now()
+	if content, ok := cachedContext(f.cache); ok {
+		return contextResult{name: f.name, content: content, elapsed: time.Since(start), cached: true}
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	type outcome struct {
+		content string
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		content, err := f.fetch(fetchCtx)
+		done <- outcome{content: content, err: err}
+	}()
+
+	select {
+	case o := <-done:
+		if o.err == nil {
+			storeContext(f.cache, o.content)
+		}
+		return contextResult{name: f.name, content: o.content, err: o.err, elapsed: time.Since(start)}
+	case <-fetchCtx.Done():
+		err := fetchCtx.Err()
+		if err == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s", f.timeout)
+		}
+		return contextResult{name: f.name, err: err, elapsed: time.Since(start)}
+	}
+}
+
+// cachedContext returns the cached inventory for key unless caching is off
+// or --refresh-context was given
+func cachedContext(key *contextCacheKey) (string, bool) {
+	if key == nil || !contextcache.Enabled() || contextcache.Refresh() {
+		return "", false
+	}
+	entry, ok := contextcache.Get(key.provider, key.profile, key.question, contextcache.TTL())
+	return entry.Content, ok
+}
+
+// storeContext caches freshly gathered inventory. Failing to write the cache
+// never fails the ask.
+func storeContext(key *contextCacheKey, content string) {
+	if key == nil || !contextcache.Enabled() || strings.TrimSpace(content) == "" {
+		return
+	}
+	if err := contextcache.Put(key.provider, key.profile, key.question, content); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to cache %s context: %v\n", key.provider, err)
+	}
+}
+
+// fetchWithContextCache runs fetch through the inventory cache, for callers
+// that gather a single provider's context outside gatherContexts
+func fetchWithContextCache(ctx context.Context, key *contextCacheKey, fetch func(context.Context) (string, error)) (string, error) {
+	if content, ok := cachedContext(key); ok {
+		return content, nil
+	}
+	content, err := fetch(ctx)
+	if err == nil {
+		storeContext(key, content)
+	}
+	return content, err
+}
+
+// contextResultsByName indexes gathered content by provider name. A failed
+// required fetch is returned as an error; other failures are reported on
+// stderr and summarised in the returned note so the model knows which
+// contexts are missing.
+func contextResultsByName(fetches []contextFetch, results []contextResult) (map[string]string, string, error) {
+	content := make(map[string]string, len(results))
+	var unavailable []string
+	for i, r := range results {
+		if r.err == nil {
+			content[r.name] = r.content
+			continue
+		}
+		if fetches[i].required {
+			return nil, "", fmt.Errorf("failed to get %s context: %w", r.name, r.err)
+		}
+		fmt.Fprintf(os.Stderr, "warning: %s context unavailable: %v\n", r.name, r.err)
+		unavailable = append(unavailable, fmt.Sprintf("- %s: %v", r.name, r.err))
+	}
+	if len(unavailable) == 0 {
+		return content, "", nil
+	}
+	return content, "Unavailable Context (answer without it and say so if it matters):\n" + strings.Join(unavailable, "\n"), nil
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestGatherContextsRunsConcurrently(t *testing.T) {
+	slow := func(content string) func(context.Context) (string, error) {
+		return func(ctx context.Context) (string, error) {
+			time.Sleep(100 * time.Millisecond)
+			return content, nil
+		}
+	}
+	fetches := []contextFetch{
+		{name: "AWS", timeout: time.Second, fetch: slow("aws")},
+		{name: "GCP", timeout: time.Second, fetch: slow("gcp")},
+		{name: "Terraform", timeout: time.Second, fetch: slow("tf")},
+	}
+
+	start := time.Now()
+	results := gatherContexts(context.Background(), fetches, false)
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("fetches ran sequentially: took %s", elapsed)
+	}
+	for i, want := range []string{"aws", "gcp", "tf"} {
+		if results[i].content != want || results[i].name != fetches[i].name {
+			t.Errorf("result %d = %+v, want content %q", i, results[i], want)
+		}
+	}
+}
+
+func TestGatherContextsPartialResults(t *testing.T) {
+	fetches := []contextFetch{
+		{name: "AWS", timeout: time.Second, fetch: func(ctx context.Context) (string, error) {
+			return "aws", nil
+		}},
+		{name: "GCP", timeout: 50 * time.Millisecond, fetch: func(ctx context.Context) (string, error) {
+			// Ignores cancellation; gatherContexts must not wait for it
+			time.Sleep(time.Second)
+			return "late", nil
+		}},
+		{name: "GitHub", timeout: time.Second, fetch: func(ctx context.Context) (string, error) {
+			return "", errors.New("bad credentials")
+		}},
+	}
+
+	start := time.Now()
+	gathered, note, err := contextResultsByName(fetches, gatherContexts(context.Background(), fetches, false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("timed-out fetch was waited on: took %s", elapsed)
+	}
+	if gathered["AWS"] != "aws" {
+		t.Errorf("AWS context = %q", gathered["AWS"])
+	}
+	if _, ok := gathered["GCP"]; ok {
+		t.Error("timed-out GCP context should be absent")
+	}
+	for _, want := range []string{"GCP: timed out after 50ms", "GitHub: bad credentials"} {
+		if !strings.Contains(note, want) {
+			t.Errorf("note missing %q:\n%s", want, note)
+		}
+	}
+}
+
+func TestContextResultsRequiredFailure(t *testing.T) {
+	fetches := []contextFetch{
+		{name: "AWS", timeout: time.Second, fetch: func(ctx context.Context) (string, error) { return "aws", nil }},
+		{name: "Database", timeout: time.Second, required: true, fetch: func(ctx context.Context) (string, error) {
+			return "", errors.New("connection refused")
+		}},
+	}
+
+	_, _, err := contextResultsByName(fetches, gatherContexts(context.Background(), fetches, false))
+	if err == nil || !strings.Contains(err.Error(), "failed to get Database context") {
+		t.Errorf("err = %v, want required Database failure", err)
+	}
+}
+
+func TestGatherContextsReusesCachedInventory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Cleanup(func() { viper.Set("context_cache.refresh", nil) })
+
+	calls := 0
+	fetches := []contextFetch{{
+		name:    "AWS",
+		timeout: time.Second,
+		cache:   &contextCacheKey{provider: "aws", profile: "prod", question: "list ec2 instances"},
+		fetch: func(ctx context.Context) (string, error) {
+			calls++
+			return "EC2 Instances:\ni-123", nil
+		},
+	}}
+
+	first := gatherContexts(context.Background(), fetches, false)
+	second := gatherContexts(context.Background(), fetches, false)
+	if calls != 1 {
+		t.Fatalf("fetch ran %d times, want 1", calls)
+	}
+	if first[0].cached || !second[0].cached || second[0].content != "EC2 Instances:\ni-123" {
+		t.Errorf("first = %+v, second = %+v", first[0], second[0])
+	}
+
+	viper.Set("context_cache.refresh", true)
+	if got := gatherContexts(context.Background(), fetches, false); got[0].cached || calls != 2 {
+		t.Errorf("--refresh-context should re-query: cached=%v calls=%d", got[0].cached, calls)
+	}
+}
+
+func TestContextTimeoutOverride(t *testing.T) {
+	t.Cleanup(func() { viper.Set("ask.context_timeouts.aws", nil) })
+
+	if got := contextTimeout("aws", awsContextTimeout); got != awsContextTimeout {
+		t.Errorf("default = %s, want %s", got, awsContextTimeout)
+	}
+	viper.Set("ask.context_timeouts.aws", "5m")
+	if got := contextTimeout("aws", awsContextTimeout); got != 5*time.Minute {
+		t.Errorf("override = %s, want 5m", got)
+	}
+	if got := contextTimeout("gcp", cloudContextTimeout); got != cloudContextTimeout {
+		t.Errorf("gcp = %s, want default %s", got, cloudContextTimeout)
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bgdnvk/clanker/internal/routing"
+	"github.com/spf13/viper"
+)
+
+// routedAgentOptions carries the ask flags the routed agents need
+type routedAgentOptions struct {
+	Debug        bool
+	Profile      string
+	DBConnection string
+	RoleARN      string
+	PolicyARN    string
+	// AzureSubscription is --azure-subscription, for the Azure agents
+	AzureSubscription string
+}
+
+// runRoutedAgent hands a question to the agent a routing decision named.
+// It reports false for agents that answer through the general CLI path.
+func runRoutedAgent(ctx context.Context, agent, question string, opts routedAgentOptions) (bool, error) {
+	switch agent {
+	case "k8s":
+		return true, handleK8sQuery(ctx, question, opts.Debug, viper.GetString("kubernetes.kubeconfig"))
+	case "iam":
+		return true, handleIAMQuery(ctx, question, opts.Debug, opts.RoleARN, opts.PolicyARN)
+	case "aws-cost":
+		return true, handleAWSCostQuery(ctx, question, opts.Debug, opts.Profile)
+	case "aws-audit":
+		return true, handleAWSAuditQuery(ctx, question, opts.Debug, opts.Profile)
+	case "aws-logs":
+		return true, handleAWSLogsQuery(ctx, question, opts.Debug, opts.Profile, awsLogsOptions{})
+	case "aws-ssm":
+		return true, handleAWSSSMQuery(ctx, question, opts.Debug, opts.Profile)
+	case "aws-rds":
+		return true, handleAWSRDSQuery(ctx, question, opts.Debug, opts.Profile)
+	case "aws-lambda":
+		return true, handleAWSLambdaQuery(ctx, question, opts.Debug, opts.Profile)
+	case "azure-infra":
+		return true, handleAzureInfraQuery(ctx, question, opts.Debug, opts.AzureSubscription)
+	case "secrets":
+		return true, handleSecretsQuery(ctx, question, opts.Debug, opts.Profile)
+	case "certs":
+		return true, handleCertsQuery(ctx, question, opts.Debug, opts.Profile)
+	case "dns-truth":
+		return true, handleDNSTruthQuery(ctx, question, opts.Debug, opts.Profile)
+	case "agent-database":
+		return true, handleDatabaseQuery(ctx, question, opts.Debug, opts.DBConnection)
+	case "github-actions":
+		return true, handleGitHubActionsQuery(ctx, question, opts.Debug)
+	case "pr-review":
+		return true, handleGitHubPRReviewQuery(ctx, question, opts.Debug)
+	case "gitlab-ci":
+		return true, handleGitLabCIQuery(ctx, question, opts.Debug)
+	case "terraform-state":
+		return true, handleTerraformStateQuery(ctx, question, opts.Debug, opts.Profile)
+	case "agent-cicd":
+		return true, handleCICDQuery(ctx, question, opts.Debug)
+	case "agent-observability":
+		return true, handleObservabilityQuery(ctx, question, opts.Debug, opts.Profile)
+	case "hermes":
+		return true, handleHermesQuery(ctx, question, opts.Profile, opts.Debug)
+	case "cloudflare":
+		return true, handleCloudflareQuery(ctx, question, opts.Debug)
+	case "digitalocean":
+		return true, handleDigitalOceanQuery(ctx, question, opts.Debug)
+	case "hetzner":
+		return true, handleHetznerQuery(ctx, question, opts.Debug)
+	case "oracle":
+		return true, handleOracleQuery(ctx, question, opts.Debug)
+	case "vercel":
+		return true, handleVercelQuery(ctx, question, opts.Debug)
+	case "flyio":
+		return true, handleFlyioQuery(ctx, question, opts.Debug)
+	case "railway":
+		return true, handleRailwayQuery(ctx, question, opts.Debug)
+	case "verda":
+		return true, handleVerdaQuery(ctx, question, opts.Debug)
+	case "sentry":
+		return true, handleSentryQuery(ctx, question, opts.Debug)
+	case "diagram":
+		return true, handleDiagramQuery(ctx, question, opts.Debug, opts.Profile)
+	case "ticket":
+		return true, handleFileTicketQuery(ctx, question, opts.Debug)
+	case "tencent":
+		return true, handleTencentQuery(ctx, question, opts.Debug)
+	}
+	if c, ok := findPlugin(agent); ok {
+		return true, handlePluginQuery(ctx, c, question, opts)
+	}
+	return false, nil
+}
+
+// runFanOutAgents asks each agent in turn, for questions that span several
+// systems. One agent failing does not stop the others.
+func runFanOutAgents(ctx context.Context, agents []string, question string, opts routedAgentOptions) error {
+	var errs []error
+	for i, agent := range agents {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("=== %s ===\n", agent)
+		if _, err := runRoutedAgent(ctx, agent, question, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "[routing] %s failed: %v\n", agent, err)
+			errs = append(errs, fmt.Errorf("%s: %w", agent, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recordRoute remembers where a question went so clanker route correct
+// --last can fix it
+func recordRoute(question, agent string, debug bool) {
+	if err := routing.RecordLast(question, agent); err != nil && debug {
+		fmt.Printf("[routing] failed to record route: %v\n", err)
+	}
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the log of changes clanker made",
+	Long: `Every clanker command that changes cloud or cluster resources through
+kubectl, helm, eksctl, aws, gcloud or az appends a record to ~/.clanker/audit.log:
+who ran it, the command, the provider, the hash of the applied plan, each
+mutating step with its exit status, and the overall result. Policy overrides
+and approvals are recorded there too.
+
+Examples:
+  clanker audit list
+  clanker audit list --provider kubernetes --failed
+  clanker audit show 3f9a1c`,
+}
+
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List audit records, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+		provider, _ := cmd.Flags().GetString("provider")
+		failed, _ := cmd.Flags().GetBool("failed")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		records, err := audit.List()
+		if err != nil {
+			return err
+		}
+		records = filterAuditRecords(records, provider, failed, limit)
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(records)
+		}
+		if len(records) == 0 {
+			fmt.Println("No audit records.")
+			return nil
+		}
+		printAuditRecords(os.Stdout, records)
+		return nil
+	},
+}
+
+var auditShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show one audit record (an unambiguous id prefix is enough)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rec, err := audit.Find(args[0])
+		if err != nil {
+			return err
+		}
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(rec)
+		}
+		printAuditRecord(os.Stdout, rec)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditListCmd)
+	auditCmd.AddCommand(auditShowCmd)
+
+	auditListCmd.Flags().Int("limit", 20, "Maximum number of records to show (0 for all)")
+	auditListCmd.Flags().String("provider", "", "Only show records for this provider (kubernetes, aws, gcp, azure)")
+	auditListCmd.Flags().Bool("failed", false, "Only show failed commands")
+	auditListCmd.Flags().Bool("json", false, "Output records as JSON")
+	auditShowCmd.Flags().Bool("json", false, "Output the record as JSON")
+}
+
+// filterAuditRecords returns the newest matching records first
+func filterAuditRecords(records []audit.Record, provider string, failed bool, limit int) []audit.Record {
+	var out []audit.Record
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := records[i]
+		if provider != "" && !strings.EqualFold(rec.Provider, provider) {
+			continue
+		}
+		if failed && !rec.Failed() {
+			continue
+		}
+		out = append(out, rec)
+		if limit > 0 && len(out) == limit {
+			break
+		}
+	}
+	return out
+}
+
+func printAuditRecords(w io.Writer, records []audit.Record) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTIME\tUSER\tEVENT\tCOMMAND\tPROVIDER\tSTEPS\tSTATUS")
+	for _, rec := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			rec.ID, rec.Time.Local().Format("2006-01-02 15:04:05"), rec.User, rec.Event,
+			rec.Command, orDash(rec.Provider), len(rec.Steps), auditStatus(rec))
+	}
+	tw.Flush()
+}
+
+func printAuditRecord(w io.Writer, rec audit.Record) {
+	fmt.Fprintf(w, "ID:        %s\n", rec.ID)
+	fmt.Fprintf(w, "Time:      %s\n", rec.Time.Local().Format("2006-01-02 15:04:05 MST"))
+	fmt.Fprintf(w, "User:      %s\n", rec.User)
+	fmt.Fprintf(w, "Event:     %s\n", rec.Event)
+	fmt.Fprintf(w, "Command:   %s\n", rec.Command)
+	if rec.Provider != "" {
+		fmt.Fprintf(w, "Provider:  %s\n", rec.Provider)
+	}
+	if rec.Summary != "" {
+		fmt.Fprintf(w, "Summary:   %s\n", rec.Summary)
+	}
+	if rec.PlanHash != "" {
+		fmt.Fprintf(w, "Plan hash: sha256:%s\n", rec.PlanHash)
+	}
+	if rec.Duration != "" {
+		fmt.Fprintf(w, "Duration:  %s\n", rec.Duration)
+	}
+	fmt.Fprintf(w, "Status:    %s\n", auditStatus(rec))
+	if rec.Error != "" {
+		fmt.Fprintf(w, "Error:     %s\n", rec.Error)
+	}
+	for _, detail := range rec.Details {
+		fmt.Fprintf(w, "  - %s\n", detail)
+	}
+	if len(rec.Steps) > 0 {
+		fmt.Fprintf(w, "\nSteps (%d):\n", len(rec.Steps))
+		for i, step := range rec.Steps {
+			fmt.Fprintf(w, "  %d. [exit %d] %s %s\n", i+1, step.ExitStatus, step.Tool, strings.Join(step.Args, " "))
+			if step.Error != "" {
+				fmt.Fprintf(w, "     error: %s\n", step.Error)
+			}
+		}
+	}
+}
+
+func auditStatus(rec audit.Record) string {
+	if rec.Failed() {
+		return fmt.Sprintf("failed (exit %d)", rec.ExitStatus)
+	}
+	return "ok"
+}
+
+// auditCommandLine names the command that ran and the flags it was given.
+// Flag values are left out so tokens passed on the command line never reach
+// the audit log.
+func auditCommandLine(cmd *cobra.Command) string {
+	if cmd == nil {
+		return "clanker"
+	}
+	parts := []string{cmd.CommandPath()}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		parts = append(parts, "--"+f.Name)
+	})
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/spf13/cobra"
+)
+
+func TestFilterAuditRecords(t *testing.T) {
+	records := []audit.Record{
+		{ID: "a", Provider: "aws"},
+		{ID: "b", Provider: "kubernetes", ExitStatus: 1},
+		{ID: "c", Provider: "kubernetes"},
+		{ID: "d", Provider: "gcp"},
+	}
+	ids := func(rs []audit.Record) string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := ids(filterAuditRecords(records, "", false, 0)); got != "d,c,b,a" {
+		t.Errorf("all = %s", got)
+	}
+	if got := ids(filterAuditRecords(records, "", false, 2)); got != "d,c" {
+		t.Errorf("limit = %s", got)
+	}
+	if got := ids(filterAuditRecords(records, "Kubernetes", false, 0)); got != "c,b" {
+		t.Errorf("provider = %s", got)
+	}
+	if got := ids(filterAuditRecords(records, "", true, 0)); got != "b" {
+		t.Errorf("failed = %s", got)
+	}
+}
+
+func TestPrintAuditRecord(t *testing.T) {
+	var buf bytes.Buffer
+	printAuditRecord(&buf, audit.Record{
+		ID:         "3f9a1c2b4d5e",
+		Event:      audit.EventApply,
+		User:       "ops",
+		Command:    "clanker ask --apply --plan-file",
+		Provider:   "kubernetes",
+		PlanHash:   "abc",
+		ExitStatus: 1,
+		Error:      "kubectl command failed",
+		Steps: []audit.Step{
+			{Tool: "kubectl", Args: []string{"delete", "pod", "web"}, ExitStatus: 1, Error: "exit status 1"},
+		},
+	})
+	out := buf.String()
+	for _, want := range []string{"Plan hash: sha256:abc", "Status:    failed (exit 1)", "1. [exit 1] kubectl delete pod web", "error: exit status 1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestAuditCommandLineOmitsFlagValues(t *testing.T) {
+	root := &cobra.Command{Use: "clanker"}
+	ask := &cobra.Command{Use: "ask", Run: func(*cobra.Command, []string) {}}
+	ask.Flags().Bool("apply", false, "")
+	ask.Flags().String("openai-key", "", "")
+	root.AddCommand(ask)
+	root.SetArgs([]string{"ask", "--apply", "--openai-key", "sk-secret", "question"})
+	cmd, err := root.ExecuteC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := auditCommandLine(cmd); got != "clanker ask --apply --openai-key" {
+		t.Errorf("auditCommandLine = %q", got)
+	}
+}
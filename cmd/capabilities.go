@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/cli"
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// k8sMakerPlanSteps returns each step's full command line with the binary
+// first, expanding the "eks" shorthand the executor runs as `aws eks`
+func k8sMakerPlanSteps(p *plan.MakerPlan) [][]string {
+	steps := make([][]string, len(p.Commands))
+	for i, c := range p.Commands {
+		if len(c.Args) > 0 && c.Args[0] == "eks" {
+			steps[i] = append([]string{"aws"}, c.Args...)
+			continue
+		}
+		steps[i] = c.Args
+	}
+	return steps
+}
+
+func stepTools(steps [][]string, defaultTool string) []string {
+	tools := make([]string, len(steps))
+	for i, args := range steps {
+		if len(args) > 0 {
+			tools[i] = cli.StepTool(args, defaultTool)
+		}
+	}
+	return tools
+}
+
+func makerPlanStepTools(p *maker.Plan) []string {
+	steps := make([][]string, len(p.Commands))
+	for i, c := range p.Commands {
+		steps[i] = c.Args
+	}
+	return stepTools(steps, cli.ProviderTool(p.Provider))
+}
+
+// k8sPlanStepTools lists helm steps before kubectl steps, matching the order
+// executeK8sPlan runs them in
+func k8sPlanStepTools(p *k8s.K8sPlan) []string {
+	tools := make([]string, 0, len(p.HelmCmds)+len(p.KubectlCmds)+len(p.Manifests))
+	for range p.HelmCmds {
+		tools = append(tools, "helm")
+	}
+	for range p.KubectlCmds {
+		tools = append(tools, "kubectl")
+	}
+	for range p.Manifests {
+		tools = append(tools, "kubectl")
+	}
+	return tools
+}
+
+// missingToolAnnotation is appended to a step's reason at plan time
+func missingToolAnnotation(reason string, req cli.ToolRequirement, args []string) string {
+	note := req.Annotation()
+	if alt, ok := cli.AlternativeCommand(args); ok && cli.ToolAvailable("aws") {
+		note += "; fallback: " + strings.Join(alt, " ")
+	}
+	if reason == "" {
+		return "[" + note + "]"
+	}
+	return reason + " [" + note + "]"
+}
+
+// missingToolNotes summarises missing tools for a plan's notes
+func missingToolNotes(missing []cli.ToolRequirement) []string {
+	notes := make([]string, 0, len(missing))
+	for _, req := range missing {
+		note := fmt.Sprintf("step(s) %s %s", cli.JoinSteps(req.Steps), req.Annotation())
+		if req.Installable {
+			note += "; clanker will offer to install it before apply"
+		} else if req.InstallHint != "" {
+			note += "; install it first: " + req.InstallHint
+		}
+		notes = append(notes, note)
+	}
+	return notes
+}
+
+// annotateK8sMakerPlanTools marks steps whose CLI is missing so the gap
+// shows up in the plan instead of half way through apply
+func annotateK8sMakerPlanTools(p *plan.MakerPlan) {
+	steps := k8sMakerPlanSteps(p)
+	missing := cli.MissingTools(cli.CheckStepTools(stepTools(steps, "")))
+	for _, req := range missing {
+		for _, step := range req.Steps {
+			c := &p.Commands[step-1]
+			c.Reason = missingToolAnnotation(c.Reason, req, steps[step-1])
+		}
+	}
+	p.Notes = append(p.Notes, missingToolNotes(missing)...)
+}
+
+// annotateMakerPlanTools is annotateK8sMakerPlanTools for provider plans
+func annotateMakerPlanTools(p *maker.Plan) {
+	missing := cli.MissingTools(cli.CheckStepTools(makerPlanStepTools(p)))
+	for _, req := range missing {
+		for _, step := range req.Steps {
+			c := &p.Commands[step-1]
+			c.Reason = missingToolAnnotation(c.Reason, req, nil)
+		}
+	}
+	p.Notes = append(p.Notes, missingToolNotes(missing)...)
+}
+
+// annotateK8sPlanTools is annotateK8sMakerPlanTools for agent plans; the
+// summary goes to Warnings, which the agent plan already surfaces
+func annotateK8sPlanTools(p *k8s.K8sPlan) {
+	missing := cli.MissingTools(cli.CheckStepTools(k8sPlanStepTools(p)))
+	for _, req := range missing {
+		for _, step := range req.Steps {
+			if step <= len(p.HelmCmds) {
+				c := &p.HelmCmds[step-1]
+				c.Reason = missingToolAnnotation(c.Reason, req, nil)
+				continue
+			}
+			c := &p.KubectlCmds[step-1-len(p.HelmCmds)]
+			c.Reason = missingToolAnnotation(c.Reason, req, nil)
+		}
+	}
+	p.Warnings = append(p.Warnings, missingToolNotes(missing)...)
+}
+
+// preflightPlanTools checks every CLI a plan needs before the first step
+// runs. For each missing tool it offers the aws CLI fallback when every
+// affected step has one, then the built-in installer; anything still missing
+// aborts the apply. steps holds full command lines for executors that can
+// take substitutions and may be nil otherwise. The returned map replaces the
+// command line of each 1-based step number the user chose to fall back for.
+func preflightPlanTools(ctx context.Context, tools []string, steps [][]string, debug bool) (map[int][]string, error) {
+	missing := cli.MissingTools(cli.CheckStepTools(tools))
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	cli.PrintMissingTools(missing)
+
+	substitutions := map[int][]string{}
+	var unresolved []string
+	for _, req := range missing {
+		if alternatives := stepAlternatives(req, steps); alternatives != nil && cli.ToolAvailable("aws") {
+			// A closed or piped stdin counts as "no"
+			if ok, _ := cli.PromptForFallback(req.Tool, alternatives); ok {
+				for step, alt := range alternatives {
+					substitutions[step] = alt
+				}
+				continue
+			}
+		}
+
+		if req.Installable {
+			ok, _ := cli.PromptForSingleInstall(cli.DependencyStatus{Name: req.Tool})
+			if ok {
+				cli.PrintInstallationStart(req.Tool)
+				if err := cli.NewInstaller(debug).Install(ctx, req.Tool, cli.DefaultInstallOptions()); err != nil {
+					cli.PrintInstallationError(req.Tool, err)
+				} else {
+					cli.PrintInstallationSuccess(req.Tool)
+					continue
+				}
+			}
+		}
+		unresolved = append(unresolved, req.Tool)
+	}
+
+	if len(unresolved) > 0 {
+		return nil, fmt.Errorf("plan not applied: missing required CLI(s): %s", strings.Join(unresolved, ", "))
+	}
+	return substitutions, nil
+}
+
+// stepAlternatives returns the aws CLI replacement for every step that needs
+// req.Tool, or nil if any of them has none
+func stepAlternatives(req cli.ToolRequirement, steps [][]string) map[int][]string {
+	if steps == nil {
+		return nil
+	}
+	alternatives := map[int][]string{}
+	for _, step := range req.Steps {
+		alt, ok := cli.AlternativeCommand(steps[step-1])
+		if !ok {
+			return nil
+		}
+		alternatives[step] = alt
+	}
+	return alternatives
+}
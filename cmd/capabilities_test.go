@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+)
+
+func TestAnnotateK8sMakerPlanToolsMarksMissingCLIs(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("CLANKER_AWS_CLI_PATH", "")
+
+	p := &plan.MakerPlan{Commands: []plan.MakerCommand{
+		{Args: []string{"eksctl", "create", "cluster", "--name", "demo"}, Reason: "Create cluster"},
+		{Args: []string{"ssh", "ubuntu@10.0.0.1", "true"}, Reason: "Check node"},
+		{Args: []string{"eks", "describe-cluster", "--name", "demo"}},
+	}}
+	annotateK8sMakerPlanTools(p)
+
+	if got := p.Commands[0].Reason; got != "Create cluster [requires eksctl (not installed)]" {
+		t.Errorf("eksctl step reason = %q", got)
+	}
+	if got := p.Commands[1].Reason; got != "Check node" {
+		t.Errorf("untracked binaries should not be annotated: %q", got)
+	}
+	if got := p.Commands[2].Reason; got != "[requires aws (not installed)]" {
+		t.Errorf("eks shorthand should require aws: %q", got)
+	}
+	if len(p.Notes) != 2 || !strings.HasPrefix(p.Notes[0], "step(s) 1 requires eksctl") || !strings.Contains(p.Notes[0], "offer to install") {
+		t.Errorf("notes = %q", p.Notes)
+	}
+}
+
+func TestPreflightPlanToolsRefusesWithoutTools(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	_, err := preflightPlanTools(context.Background(), []string{"helm", "", "helm"}, nil, false)
+	if err == nil || !strings.Contains(err.Error(), "missing required CLI(s): helm") {
+		t.Fatalf("err = %v", err)
+	}
+	if subs, err := preflightPlanTools(context.Background(), []string{"", ""}, nil, false); err != nil || subs != nil {
+		t.Errorf("plans without tracked tools should pass: %v %v", subs, err)
+	}
+}
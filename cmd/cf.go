@@ -8,6 +8,7 @@ import (
 
 	"github.com/bgdnvk/clanker/internal/ai"
 	"github.com/bgdnvk/clanker/internal/cloudflare"
+	cfanalytics "github.com/bgdnvk/clanker/internal/cloudflare/analytics"
 	"github.com/bgdnvk/clanker/internal/cloudflare/dns"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -40,6 +41,7 @@ var (
 	cfAskZone      string
 	cfAskAIProfile string
 	cfAskDebug     bool
+	cfAskRefresh   bool
 )
 
 func init() {
@@ -52,6 +54,7 @@ func init() {
 	cfAskCmd.Flags().StringVar(&cfAskZone, "zone", "", "Default zone name for zone-specific queries")
 	cfAskCmd.Flags().StringVar(&cfAskAIProfile, "ai-profile", "", "AI profile to use for LLM queries")
 	cfAskCmd.Flags().BoolVar(&cfAskDebug, "debug", false, "Enable debug output")
+	cfAskCmd.Flags().BoolVar(&cfAskRefresh, "refresh-context", false, "Re-query Cloudflare instead of reusing ~/.clanker/cache")
 }
 
 // AddCfAskCommand adds the ask subcommand to the cf command
@@ -66,6 +69,9 @@ func runCfAsk(cmd *cobra.Command, args []string) error {
 	}
 
 	debug := cfAskDebug || viper.GetBool("debug")
+	if cfAskRefresh {
+		setFlagOverride("context_cache.refresh", true)
+	}
 
 	// Resolve account ID
 	accountID := cfAskAccountID
@@ -133,6 +139,9 @@ func handleCfQuery(ctx context.Context, client *cloudflare.Client, question stri
 	questionLower := strings.ToLower(question)
 
 	// Route to appropriate subagent based on query content
+	if cfanalytics.IsSecurityEventQuery(question) {
+		return handleCfSecurityEventsQuery(ctx, client, question, debug)
+	}
 	if isDNSQuery(questionLower) {
 		return handleDNSQuery(ctx, client, question, debug)
 	}
@@ -141,6 +150,22 @@ func handleCfQuery(ctx context.Context, client *cloudflare.Client, question stri
 	return handleGeneralCfQuery(ctx, client, question, history, debug)
 }
 
+// handleCfSecurityEventsQuery answers "show me blocked requests in the
+// last hour" style questions, tailing when the question asks to follow
+func handleCfSecurityEventsQuery(ctx context.Context, client *cloudflare.Client, question string, debug bool) (string, error) {
+	q := cfanalytics.ParseEventQuery(question)
+	if q.ZoneName == "" {
+		q.ZoneName = cfAskZone
+	}
+	if q.ZoneName == "" {
+		return "", fmt.Errorf("zone is required for security events (name it in the question or pass --zone)")
+	}
+	if q.Tail {
+		return "", tailCloudflareSecurityEvents(ctx, client, q, cfanalytics.DefaultTailInterval, debug)
+	}
+	return cloudflareSecurityEvents(ctx, client, q, debug)
+}
+
 func isDNSQuery(query string) bool {
 	dnsKeywords := []string{
 		"dns", "zone", "record", "a record", "aaaa", "cname", "mx", "txt",
@@ -206,7 +231,9 @@ func formatDNSPlan(plan *dns.Plan) string {
 
 func handleGeneralCfQuery(ctx context.Context, client *cloudflare.Client, question string, history *cloudflare.ConversationHistory, debug bool) (string, error) {
 	// Get relevant context from Cloudflare
-	cfContext, err := client.GetRelevantContext(ctx, question)
+	cfContext, err := fetchWithContextCache(ctx, &contextCacheKey{provider: "cloudflare", profile: client.GetAccountID(), question: question}, func(ctx context.Context) (string, error) {
+		return client.GetRelevantContext(ctx, question)
+	})
 	if err != nil && debug {
 		fmt.Printf("[debug] failed to get Cloudflare context: %v\n", err)
 	}
@@ -175,6 +175,8 @@ func AddCfDeployCommands(cfCmd *cobra.Command) {
 	cfCmd.AddCommand(cfDeployCmd)
 	cfCmd.AddCommand(cfCreateCmd)
 	cfCmd.AddCommand(cfDeleteCmd)
+	cfCmd.AddCommand(cfDNSCmd)
+	cfCmd.AddCommand(cfEventsCmd)
 }
 
 func getCfClient() (*cloudflare.Client, error) {
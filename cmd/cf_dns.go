@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/cloudflare"
+	cfdns "github.com/bgdnvk/clanker/internal/cloudflare/dns"
+	"github.com/bgdnvk/clanker/internal/planstore"
+	"github.com/spf13/cobra"
+)
+
+var cfDNSCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "Export and import a zone's DNS records",
+	Long: `Export every DNS record in a zone as a BIND zone file or JSON, or plan a
+bulk import that makes a zone match a zone file.
+
+Examples:
+  clanker cf dns export --zone example.com > example.com.zone
+  clanker cf dns export --zone example.com --format json
+  clanker cf dns import --zone example.com --file ./example.com.zone
+  clanker cf dns import --zone example.com --file ./records.json --prune`,
+}
+
+var cfDNSExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Print every DNS record in a zone as BIND or JSON",
+	RunE:  runCfDNSExport,
+}
+
+var cfDNSImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Plan the creates, updates and deletes that make a zone match a zone file",
+	Long: `Diff a BIND zone file or JSON export against a zone's current records and
+print a plan of create, update and delete steps. Records keep their current
+TTL and proxy status unless the file sets them; Cloudflare's own exports
+carry proxy status in cf_tags comments. Records missing from the file are
+deleted only with --prune. Nothing changes until the plan is applied with
+clanker ask --apply.`,
+	RunE: runCfDNSImport,
+}
+
+var (
+	cfDNSIOZone   string
+	cfDNSIOFormat string
+	cfDNSIOFile   string
+	cfDNSIOPrune  bool
+)
+
+func init() {
+	cfDNSCmd.PersistentFlags().StringVar(&cfDeployAccountID, "account-id", "", "Cloudflare account ID")
+	cfDNSCmd.PersistentFlags().StringVar(&cfDeployAPIToken, "api-token", "", "Cloudflare API token")
+	cfDNSCmd.PersistentFlags().BoolVar(&cfDeployDebug, "debug", false, "Enable debug output")
+	cfDNSCmd.PersistentFlags().StringVar(&cfDNSIOZone, "zone", "", "Zone name, e.g. example.com (required)")
+	_ = cfDNSCmd.MarkPersistentFlagRequired("zone")
+
+	cfDNSExportCmd.Flags().StringVar(&cfDNSIOFormat, "format", "bind", "Output format: bind or json")
+
+	cfDNSImportCmd.Flags().StringVar(&cfDNSIOFile, "file", "", "BIND zone file or JSON export to import (required)")
+	cfDNSImportCmd.Flags().BoolVar(&cfDNSIOPrune, "prune", false, "Delete records the file does not have")
+	_ = cfDNSImportCmd.MarkFlagRequired("file")
+
+	cfDNSCmd.AddCommand(cfDNSExportCmd)
+	cfDNSCmd.AddCommand(cfDNSImportCmd)
+}
+
+func runCfDNSExport(cmd *cobra.Command, args []string) error {
+	format := strings.ToLower(strings.TrimSpace(cfDNSIOFormat))
+	if format != "bind" && format != "json" {
+		return fmt.Errorf("unknown format %q (use bind or json)", cfDNSIOFormat)
+	}
+	client, err := getCfClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	out, err := cfdns.NewSubAgent(client, cfDeployDebug).Export(ctx, cfDNSIOZone, format)
+	if err != nil {
+		return err
+	}
+	fmt.Println(strings.TrimRight(out, "\n"))
+	return nil
+}
+
+func runCfDNSImport(cmd *cobra.Command, args []string) error {
+	client, err := getCfClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	question := fmt.Sprintf("import %s into %s", cfDNSIOFile, cfDNSIOZone)
+	return handleCloudflareDNSImport(ctx, client, cfdns.ImportRequest{Zone: cfDNSIOZone, File: cfDNSIOFile, Prune: cfDNSIOPrune}, "cf dns import", question, cfDeployDebug)
+}
+
+// handleCloudflareDNSImport reads the zone file req names and prints the
+// plan that imports it
+func handleCloudflareDNSImport(ctx context.Context, client *cloudflare.Client, req cfdns.ImportRequest, source, question string, debug bool) error {
+	if req.Zone == "" {
+		return fmt.Errorf("name the zone to import into, e.g. \"import %s into example.com\"", req.File)
+	}
+	path := req.File
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read zone file: %w", err)
+	}
+	desired, err := cfdns.ParseZoneFile(req.Zone, data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", req.File, err)
+	}
+
+	plan, err := cfdns.NewSubAgent(client, debug).ImportPlan(ctx, req.Zone, desired, req.Prune, question, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(plan.Commands) == 0 {
+		fmt.Println(plan.Summary)
+		return nil
+	}
+	planJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", plan.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks(source, planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, "cloudflare", source, question, plan.Summary, planJSON)
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
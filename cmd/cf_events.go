@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/cloudflare"
+	cfanalytics "github.com/bgdnvk/clanker/internal/cloudflare/analytics"
+	"github.com/spf13/cobra"
+)
+
+var cfEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show or tail requests the firewall blocked, challenged or logged",
+	Long: `Show security events for a zone from the Security Events API: the total,
+the rules, countries and paths behind most of them, and the latest events.
+With --tail, keep polling and print new events as they arrive until
+interrupted.
+
+Examples:
+  clanker cf events --zone example.com
+  clanker cf events --zone example.com --since 24h --country CN --path "/wp-*"
+  clanker cf events --zone example.com --action managed_challenge --rule 6179ae15870a4bb7b2d480d4843b323c
+  clanker cf events --zone example.com --tail`,
+	RunE: runCfEvents,
+}
+
+var (
+	cfEventsZone     string
+	cfEventsActions  []string
+	cfEventsRule     string
+	cfEventsCountry  string
+	cfEventsPath     string
+	cfEventsSince    time.Duration
+	cfEventsLimit    int
+	cfEventsTail     bool
+	cfEventsInterval time.Duration
+)
+
+func init() {
+	cfEventsCmd.Flags().StringVar(&cfDeployAccountID, "account-id", "", "Cloudflare account ID")
+	cfEventsCmd.Flags().StringVar(&cfDeployAPIToken, "api-token", "", "Cloudflare API token")
+	cfEventsCmd.Flags().BoolVar(&cfDeployDebug, "debug", false, "Enable debug output")
+	cfEventsCmd.Flags().StringVar(&cfEventsZone, "zone", "", "Zone name, e.g. example.com (required)")
+	cfEventsCmd.Flags().StringSliceVar(&cfEventsActions, "action", []string{"block"}, "Firewall actions to include: block, challenge, managed_challenge, jschallenge, log, skip, or all")
+	cfEventsCmd.Flags().StringVar(&cfEventsRule, "rule", "", "Only events matching this rule ID")
+	cfEventsCmd.Flags().StringVar(&cfEventsCountry, "country", "", "Only events from this country (ISO code, e.g. CN)")
+	cfEventsCmd.Flags().StringVar(&cfEventsPath, "path", "", "Only events for this request path; * matches anything")
+	cfEventsCmd.Flags().DurationVar(&cfEventsSince, "since", time.Hour, "How far back to look")
+	cfEventsCmd.Flags().IntVar(&cfEventsLimit, "limit", 50, "Number of individual events to list")
+	cfEventsCmd.Flags().BoolVar(&cfEventsTail, "tail", false, "Keep printing new events until interrupted")
+	cfEventsCmd.Flags().DurationVar(&cfEventsInterval, "interval", cfanalytics.DefaultTailInterval, "Polling interval for --tail")
+	_ = cfEventsCmd.MarkFlagRequired("zone")
+}
+
+func runCfEvents(cmd *cobra.Command, args []string) error {
+	client, err := getCfClient()
+	if err != nil {
+		return err
+	}
+
+	q := cfanalytics.EventQuery{
+		ZoneName: cfEventsZone,
+		RuleID:   strings.TrimSpace(cfEventsRule),
+		Country:  strings.ToUpper(strings.TrimSpace(cfEventsCountry)),
+		Path:     strings.TrimSpace(cfEventsPath),
+		Window:   cfEventsSince,
+		Limit:    cfEventsLimit,
+		Tail:     cfEventsTail,
+	}
+	for _, a := range cfEventsActions {
+		if a = strings.ToLower(strings.TrimSpace(a)); a != "" && a != "all" {
+			q.Actions = append(q.Actions, a)
+		}
+	}
+	if len(q.Actions) < len(cfEventsActions) {
+		// "all" anywhere in the list drops the action filter
+		q.Actions = nil
+	}
+	if q.Window <= 0 {
+		return fmt.Errorf("--since must be positive")
+	}
+
+	if q.Tail {
+		return tailCloudflareSecurityEvents(cmd.Context(), client, q, cfEventsInterval, cfDeployDebug)
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 2*time.Minute)
+	defer cancel()
+	out, err := cloudflareSecurityEvents(ctx, client, q, cfDeployDebug)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+	return nil
+}
+
+// cloudflareSecurityEvents renders the security events q selects
+func cloudflareSecurityEvents(ctx context.Context, client *cloudflare.Client, q cfanalytics.EventQuery, debug bool) (string, error) {
+	agent := cfanalytics.NewSubAgent(client, debug)
+	zoneID, err := client.GetZoneIDByName(ctx, q.ZoneName)
+	if err != nil {
+		return "", fmt.Errorf("failed to find zone: %w", err)
+	}
+	events, err := agent.SecurityEvents(ctx, zoneID, q, time.Now())
+	if err != nil {
+		return "", err
+	}
+	return events.Format(), nil
+}
+
+// tailCloudflareSecurityEvents prints new security events q selects as
+// they arrive, until the user interrupts it
+func tailCloudflareSecurityEvents(parent context.Context, client *cloudflare.Client, q cfanalytics.EventQuery, interval time.Duration, debug bool) error {
+	if parent == nil {
+		parent = context.Background()
+	}
+	if q.ZoneName == "" {
+		return fmt.Errorf("name the zone to tail, e.g. \"tail blocked requests for example.com\"")
+	}
+	// A tail runs until interrupted, past any deadline the caller set
+	ctx, stop := signal.NotifyContext(context.WithoutCancel(parent), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	zoneID, err := client.GetZoneIDByName(ctx, q.ZoneName)
+	if err != nil {
+		return fmt.Errorf("failed to find zone: %w", err)
+	}
+	if interval <= 0 {
+		interval = cfanalytics.DefaultTailInterval
+	}
+	fmt.Printf("Tailing security events for %s (%s) every %s; Ctrl-C to stop\n", q.ZoneName, q.Describe(), interval)
+	return cfanalytics.NewSubAgent(client, debug).TailSecurityEvents(ctx, zoneID, q, interval, func(batch cfanalytics.TailBatch) {
+		if batch.Err != nil {
+			fmt.Fprintf(os.Stderr, "[events] poll failed: %v\n", batch.Err)
+			return
+		}
+		for _, e := range batch.Events {
+			fmt.Println(cfanalytics.FormatEventLine(e))
+		}
+	})
+}
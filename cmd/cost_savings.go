@@ -66,6 +66,8 @@ func runCostSavings(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("savings recommendations failed: %w", err)
 	}
 
+	runReportHooks("cost savings", report)
+
 	switch strings.ToLower(costFormat) {
 	case "json":
 		enc := json.NewEncoder(os.Stdout)
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"github.com/bgdnvk/clanker/internal/daemon"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run a warm background clanker process for faster asks",
+	Long: `Run clanker as a long-lived daemon listening on ~/.clanker/daemon.sock.
+
+While the daemon is up, "clanker ask" forwards to it instead of starting
+cold, reusing cached AWS credentials, Kubernetes discovery data, and open
+AI HTTP connections. Commands run one at a time inside the daemon with the
+caller's working directory and environment. If the daemon is not running,
+asks run locally as usual.
+
+Start it in the background with your shell or service manager:
+
+  clanker daemon &
+  clanker daemon status
+  clanker daemon stop
+
+Set CLANKER_NO_DAEMON=1 to bypass a running daemon for one command.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socketPath, err := daemon.SocketPath()
+		if err != nil {
+			return err
+		}
+		debug := viper.GetBool("debug")
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Printf("clanker daemon listening on %s (pid %d)\n", socketPath, os.Getpid())
+		return daemon.NewServer(socketPath, runInProcess, debug).Serve(ctx)
+	},
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the clanker daemon is running",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socketPath, err := daemon.SocketPath()
+		if err != nil {
+			return err
+		}
+		err = daemon.Forward(socketPath, daemon.Request{Control: "ping"}, os.Stdout, os.Stderr)
+		if errors.Is(err, daemon.ErrNotRunning) {
+			fmt.Println("clanker daemon is not running")
+			return nil
+		}
+		return err
+	},
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running clanker daemon",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		socketPath, err := daemon.SocketPath()
+		if err != nil {
+			return err
+		}
+		err = daemon.Forward(socketPath, daemon.Request{Control: "shutdown"}, os.Stdout, os.Stderr)
+		if errors.Is(err, daemon.ErrNotRunning) {
+			fmt.Println("clanker daemon is not running")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println("clanker daemon stopped")
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonStopCmd)
+}
+
+// forwardToDaemon runs eligible commands in a warm daemon when one is
+// listening. It reports false when the command should run locally.
+func forwardToDaemon(args []string) (bool, error) {
+	if os.Getenv("CLANKER_NO_DAEMON") != "" || !daemonEligible(args) {
+		return false, nil
+	}
+	socketPath, err := daemon.SocketPath()
+	if err != nil {
+		return false, nil
+	}
+	cwd, _ := os.Getwd()
+	err = daemon.Forward(socketPath, daemon.Request{Args: args, Cwd: cwd, Env: os.Environ()}, os.Stdout, os.Stderr)
+	if errors.Is(err, daemon.ErrNotRunning) {
+		return false, nil
+	}
+	return true, err
+}
+
+// daemonEligible limits forwarding to non-interactive asks. Anything that
+// can prompt on stdin (--apply, k8s approvals) runs locally.
+func daemonEligible(args []string) bool {
+	if len(args) == 0 || args[0] != "ask" {
+		return false
+	}
+	for _, arg := range args[1:] {
+		switch arg {
+		case "--apply", "--help", "-h":
+			return false
+		}
+	}
+	return true
+}
+
+// runInProcess executes one forwarded invocation against rootCmd. Output
+// written straight to os.Stdout/os.Stderr is captured through pipes, flags
+// are reset so values from the previous run do not leak, and panics are
+// turned into errors so one bad command cannot take the daemon down.
+func runInProcess(args []string, stdout, stderr io.Writer) (err error) {
+	resetCommandFlags(rootCmd)
+	defer restoreFlagOverrides()
+
+	origStdout, origStderr := os.Stdout, os.Stderr
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		outR.Close()
+		outW.Close()
+		return err
+	}
+	os.Stdout, os.Stderr = outW, errW
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); _, _ = io.Copy(stdout, outR) }()
+	go func() { defer wg.Done(); _, _ = io.Copy(stderr, errR) }()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("command panicked: %v", r)
+		}
+		outW.Close()
+		errW.Close()
+		wg.Wait()
+		outR.Close()
+		errR.Close()
+		os.Stdout, os.Stderr = origStdout, origStderr
+		rootCmd.SetArgs(nil)
+	}()
+
+	rootCmd.SetArgs(args)
+	return rootCmd.Execute()
+}
+
+// flagOverrides remembers, for each config key a command set from its
+// flags, the override the key had before, or nil when it had none
+var flagOverrides = struct {
+	sync.Mutex
+	prev map[string]any
+}{prev: map[string]any{}}
+
+// setFlagOverride sets a config key from a command's flag for the rest of
+// the run. The daemon runs every forwarded command in one process, so the
+// key is put back once the run ends; see restoreFlagOverrides.
+func setFlagOverride(key string, value any) {
+	flagOverrides.Lock()
+	defer flagOverrides.Unlock()
+	if _, ok := flagOverrides.prev[key]; !ok {
+		flagOverrides.prev[key] = currentOverride(key)
+	}
+	viper.Set(key, value)
+}
+
+// currentOverride returns the value viper.Set gave key, or nil when its
+// value comes from the config file, the environment or a default
+func currentOverride(key string) any {
+	value := viper.Get(key)
+	// A nil override falls through to the other sources
+	viper.Set(key, nil)
+	if reflect.DeepEqual(viper.Get(key), value) {
+		return nil
+	}
+	viper.Set(key, value)
+	return value
+}
+
+// restoreFlagOverrides puts back every key setFlagOverride changed
+func restoreFlagOverrides() {
+	flagOverrides.Lock()
+	defer flagOverrides.Unlock()
+	for key, value := range flagOverrides.prev {
+		viper.Set(key, value)
+		delete(flagOverrides.prev, key)
+	}
+}
+
+// resetCommandFlags restores every flag in the command tree to its default
+func resetCommandFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			_ = sv.Replace(nil)
+			if f.DefValue != "[]" && f.DefValue != "" {
+				_ = f.Value.Set(f.DefValue[1 : len(f.DefValue)-1])
+			}
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, child := range cmd.Commands() {
+		resetCommandFlags(child)
+	}
+}
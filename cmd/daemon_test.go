@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestDaemonEligible(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"ask", "list my ec2 instances"}, true},
+		{[]string{"ask", "--aws", "what is running"}, true},
+		{[]string{"ask", "--apply", "--plan-file", "plan.json"}, false},
+		{[]string{"ask", "--help"}, false},
+		{[]string{"k8s", "ask", "scale web to 3"}, false},
+		{[]string{"daemon"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := daemonEligible(tt.args); got != tt.want {
+			t.Errorf("daemonEligible(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRunInProcessCapturesOutputAndResetsFlags(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if err := runInProcess([]string{"version"}, &stdout, &stderr); err != nil {
+		t.Fatalf("runInProcess: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "clanker version") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+
+	debugFlag := rootCmd.PersistentFlags().Lookup("debug")
+	if err := debugFlag.Value.Set("true"); err != nil {
+		t.Fatal(err)
+	}
+	debugFlag.Changed = true
+	resetCommandFlags(rootCmd)
+	if debugFlag.Value.String() != "false" || debugFlag.Changed {
+		t.Errorf("debug flag not reset: %s changed=%v", debugFlag.Value.String(), debugFlag.Changed)
+	}
+}
+
+func TestRunInProcessDoesNotCarryAskFlagsOver(t *testing.T) {
+	viper.Set("github.repos", []string{"acme/configured"})
+	t.Cleanup(func() { viper.Set("github.repos", nil) })
+
+	var stdout, stderr bytes.Buffer
+	first := []string{"ask", "--route-only", "--refresh-context", "--all-accounts", "--repo", "acme/api", "--create-ticket", "linear", "list ec2 instances"}
+	if err := runInProcess(first, &stdout, &stderr); err != nil {
+		t.Fatalf("first ask: %v\n%s", err, stderr.String())
+	}
+	if err := runInProcess([]string{"ask", "--route-only", "list ec2 instances"}, &stdout, &stderr); err != nil {
+		t.Fatalf("second ask: %v\n%s", err, stderr.String())
+	}
+
+	for _, key := range []string{"context_cache.refresh", "aws.organization.all_accounts", "ask.create_ticket"} {
+		if viper.IsSet(key) {
+			t.Errorf("%s = %v after the asks, want it unset", key, viper.Get(key))
+		}
+	}
+	if got := viper.GetStringSlice("github.repos"); len(got) != 1 || got[0] != "acme/configured" {
+		t.Errorf("github.repos = %v, want the value from before the asks", got)
+	}
+}
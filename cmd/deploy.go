@@ -19,6 +19,7 @@ import (
 	"github.com/bgdnvk/clanker/internal/deploy"
 	"github.com/bgdnvk/clanker/internal/maker"
 	"github.com/bgdnvk/clanker/internal/openclaw"
+	"github.com/bgdnvk/clanker/internal/planrun"
 	"github.com/bgdnvk/clanker/internal/resourcedb"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -42,6 +43,9 @@ Examples:
 		// Create deployment context with 20-minute timeout
 		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Minute)
 		defer cancel()
+		// Ctrl-C stops the apply after killing the running command
+		ctx, stop := planrun.WithInterrupt(ctx)
+		defer stop()
 		debug := viper.GetBool("debug")
 		profile, _ := cmd.Flags().GetString("profile")
 		applyMode, _ := cmd.Flags().GetBool("apply")
@@ -72,7 +76,7 @@ Examples:
 		enforceImageDeploy, _ := cmd.Flags().GetBool("enforce-image-deploy")
 
 		if strings.TrimSpace(localModelInferenceURL) != "" {
-			viper.Set("ai.providers.openai.local_model_inference_url", strings.TrimSpace(localModelInferenceURL))
+			setFlagOverride("ai.providers.openai.local_model_inference_url", strings.TrimSpace(localModelInferenceURL))
 		}
 
 		// 1. Clone + analyze
@@ -1138,7 +1142,7 @@ Examples:
 
 		if !applyMode {
 			fmt.Println(string(planJSON))
-			return nil
+			return runPlanGeneratedHooks("deploy", planJSON)
 		}
 
 		if isOpenClawDeploy && openClawUnresolvedApplyBlock {
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bgdnvk/clanker/internal/aws"
+	awsrds "github.com/bgdnvk/clanker/internal/aws/rds"
+	"github.com/bgdnvk/clanker/internal/diagram"
+	"github.com/bgdnvk/clanker/internal/k8s"
+)
+
+// shouldRouteToDiagramAgent reports whether a question asks to draw, update
+// or export an architecture diagram
+func shouldRouteToDiagramAgent(question string) bool {
+	return diagram.IsDiagramQuestion(question)
+}
+
+// handleDiagramQuery draws the current cluster and the RDS inventory, or
+// changes the saved diagram a question names, and prints it. The summary
+// goes to stderr so the diagram itself can be redirected to a file.
+func handleDiagramQuery(ctx context.Context, question string, debug bool, profile string) error {
+	targetProfile := resolveAWSProfile(profile)
+	if debug {
+		fmt.Printf("Delegating query to diagram agent (profile %s)...\n", targetProfile)
+	}
+
+	agent := diagram.NewAgent(diagram.Sources{
+		Cluster: func(ctx context.Context, namespace string) (*k8s.ClusterResources, error) {
+			// Listing stays cluster-wide, so keep other namespaces' pods out
+			// of the listing and the summary threshold count
+			if namespace != "" {
+				nsSelector := "metadata.namespace=" + namespace
+				if k8sFieldSelector != "" {
+					nsSelector = k8sFieldSelector + "," + nsSelector
+				}
+				k8sFieldSelector = nsSelector
+			}
+			return loadClusterResources(ctx, debug)
+		},
+		RDS: func(ctx context.Context) (*awsrds.Inventory, error) {
+			awsClient, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create AWS client with profile %s: %w", targetProfile, err)
+			}
+			return awsrds.NewAgent(awsClient.ExecCLI, debug).Describe(ctx)
+		},
+	})
+
+	result, err := agent.HandleQuery(ctx, question)
+	if err != nil {
+		return fmt.Errorf("diagram agent error: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, result.Summary)
+	fmt.Print(result.Rendered)
+	return nil
+}
@@ -155,6 +155,9 @@ func configuredGitHubReposForCICDAgent() []string {
 	if tracked := configuredGitHubTrackedRepos(); len(tracked) > 0 {
 		return tracked
 	}
+	if repos := ghclient.ConfiguredRepos(); len(repos) > 0 {
+		return repos
+	}
 	owner := strings.TrimSpace(viper.GetString("github.owner"))
 	repo := strings.TrimSpace(viper.GetString("github.repo"))
 	if owner != "" && repo != "" {
@@ -1241,10 +1244,15 @@ func collectCICDAgentContext(ctx context.Context, question string, debug bool) (
 				sections = appendDomainSection(sections, "GitHub Actions", githubInfo)
 			}
 		} else {
+			if expanded, err := ghclient.NewClient(githubToken, "", "").ExpandRepos(ctx, trackedRepos); err != nil {
+				warnings = appendDomainWarning(warnings, "GitHub CI/CD inventory", err)
+			} else {
+				trackedRepos = expanded
+			}
 			foundGitHubEvidence := false
 			for _, fullName := range trackedRepos {
 				owner, repo, ok := splitGitHubTrackedRepo(fullName)
-				if !ok {
+				if !ok || repo == "*" {
 					continue
 				}
 				githubClient := ghclient.NewClient(githubToken, owner, repo)
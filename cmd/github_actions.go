@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	ghclient "github.com/bgdnvk/clanker/internal/github"
+	ghactions "github.com/bgdnvk/clanker/internal/github/actions"
+	"github.com/bgdnvk/clanker/internal/planstore"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var githubDispatchCmd = &cobra.Command{
+	Use:   "dispatch <workflow>",
+	Short: "Plan a workflow_dispatch run of a workflow",
+	Long: `Plan a manual run of a workflow that has a workflow_dispatch trigger. The
+repository, ref and workflow are checked against GitHub first, and inputs
+are checked against the ones the workflow declares on that ref. Nothing runs
+until the plan is applied with clanker ask --apply.
+
+Examples:
+  clanker github dispatch deploy.yml --ref main -f environment=staging
+  clanker github dispatch Release --repo acme/shop --ref v1.4.0`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		fields, _ := cmd.Flags().GetStringArray("field")
+		inputs := map[string]string{}
+		for _, f := range fields {
+			name, value, ok := strings.Cut(f, "=")
+			if !ok || strings.TrimSpace(name) == "" {
+				return fmt.Errorf("invalid input %q (use name=value)", f)
+			}
+			inputs[strings.TrimSpace(name)] = value
+		}
+		ref, _ := cmd.Flags().GetString("ref")
+		req := ghactions.Request{Op: ghactions.Dispatch, Workflow: args[0], Ref: strings.TrimSpace(ref), Inputs: inputs}
+		question := fmt.Sprintf("trigger %s", args[0])
+		if req.Ref != "" {
+			question += " on " + req.Ref
+		}
+		return runGitHubActionCommand(cmd, req, question)
+	},
+}
+
+var githubRerunCmd = &cobra.Command{
+	Use:   "rerun [run-id]",
+	Short: "Plan a re-run of a workflow run's failed jobs",
+	Long: `Plan a re-run of the failed jobs of a run, or of every job with --all. Without
+a run ID the newest failed run is used, narrowed by --workflow and --branch.
+
+Examples:
+  clanker github rerun 9876543210
+  clanker github rerun --workflow ci.yml --branch main
+  clanker github rerun 9876543210 --all`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		req, err := githubRunRequest(cmd, ghactions.Rerun, args)
+		if err != nil {
+			return err
+		}
+		req.AllJobs = all
+		return runGitHubActionCommand(cmd, req, "re-run "+githubRunQuestion(req))
+	},
+}
+
+var githubCancelCmd = &cobra.Command{
+	Use:   "cancel [run-id]",
+	Short: "Plan cancelling a queued or running workflow run",
+	Long: `Plan cancelling a run. Without a run ID the newest queued or running run is
+used, narrowed by --workflow and --branch.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		req, err := githubRunRequest(cmd, ghactions.Cancel, args)
+		if err != nil {
+			return err
+		}
+		return runGitHubActionCommand(cmd, req, "cancel "+githubRunQuestion(req))
+	},
+}
+
+var githubApproveCmd = &cobra.Command{
+	Use:   "approve [run-id]",
+	Short: "Plan approving a run's pending deployments",
+	Long: `Plan approving the deployments a run waits on. Without --environment every
+pending environment you can approve is included. Without a run ID the newest
+waiting run is used, narrowed by --workflow and --branch.
+
+Examples:
+  clanker github approve 9876543210 --environment production
+  clanker github approve --workflow deploy.yml --comment "Checked staging"`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		req, err := githubRunRequest(cmd, ghactions.Approve, args)
+		if err != nil {
+			return err
+		}
+		req.Environments, _ = cmd.Flags().GetStringSlice("environment")
+		req.Comment, _ = cmd.Flags().GetString("comment")
+		return runGitHubActionCommand(cmd, req, "approve pending deployments of "+githubRunQuestion(req))
+	},
+}
+
+func init() {
+	githubCmd.AddCommand(githubDispatchCmd, githubRerunCmd, githubCancelCmd, githubApproveCmd)
+	for _, c := range []*cobra.Command{githubDispatchCmd, githubRerunCmd, githubCancelCmd, githubApproveCmd} {
+		c.Flags().StringP("repo", "r", "", "Repository as owner/name (default: github.owner/github.repo, or the current directory's repository)")
+		c.Flags().Bool("debug", false, "Enable debug output")
+	}
+	githubDispatchCmd.Flags().String("ref", "", "Branch or tag to run on (default: the repository's default branch)")
+	githubDispatchCmd.Flags().StringArrayP("field", "f", nil, "Workflow input as name=value (repeatable)")
+	for _, c := range []*cobra.Command{githubRerunCmd, githubCancelCmd, githubApproveCmd} {
+		c.Flags().String("workflow", "", "Without a run ID, only consider runs of this workflow")
+		c.Flags().String("branch", "", "Without a run ID, only consider runs on this branch")
+	}
+	githubRerunCmd.Flags().Bool("all", false, "Re-run every job, not only the failed ones")
+	githubApproveCmd.Flags().StringSlice("environment", nil, "Environments to approve (default: every pending one you can approve)")
+	githubApproveCmd.Flags().String("comment", "", "Review comment (default: "+strconv.Quote(ghactions.DefaultApprovalComment)+")")
+}
+
+// githubRunRequest reads the run ID argument and the run filters shared by
+// rerun, cancel and approve
+func githubRunRequest(cmd *cobra.Command, op ghactions.Operation, args []string) (ghactions.Request, error) {
+	req := ghactions.Request{Op: op}
+	if len(args) == 1 {
+		id, err := strconv.ParseInt(strings.TrimSpace(args[0]), 10, 64)
+		if err != nil || id <= 0 {
+			return req, fmt.Errorf("invalid run ID %q", args[0])
+		}
+		req.RunID = id
+	}
+	req.Workflow, _ = cmd.Flags().GetString("workflow")
+	req.Ref, _ = cmd.Flags().GetString("branch")
+	return req, nil
+}
+
+// githubRunQuestion describes the run a request targets, for the plan's
+// question
+func githubRunQuestion(req ghactions.Request) string {
+	if req.RunID != 0 {
+		return fmt.Sprintf("run %d", req.RunID)
+	}
+	q := "the latest run"
+	if req.Workflow != "" {
+		q += " of " + req.Workflow
+	}
+	if req.Ref != "" {
+		q += " on " + req.Ref
+	}
+	return q
+}
+
+func runGitHubActionCommand(cmd *cobra.Command, req ghactions.Request, question string) error {
+	repo, _ := cmd.Flags().GetString("repo")
+	debug, _ := cmd.Flags().GetBool("debug")
+	req.Repo = strings.TrimSpace(repo)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	return handleGitHubActionRequest(ctx, req, "github "+cmd.Name(), question, debug)
+}
+
+// shouldRouteToGitHubActionsAgent reports whether a question asks to
+// trigger a workflow or to re-run, cancel or approve a run
+func shouldRouteToGitHubActionsAgent(question string) bool {
+	return ghactions.IsActionQuestion(question)
+}
+
+// handleGitHubActionsQuery prints the plan for the GitHub Actions change a
+// question asks for. Plans are never applied here.
+func handleGitHubActionsQuery(ctx context.Context, question string, debug bool) error {
+	return handleGitHubActionRequest(ctx, ghactions.ParseRequest(question), "ask github-actions", question, debug)
+}
+
+// handleGitHubActionRequest confirms req against GitHub and prints its
+// plan. Requests that name no repository use github.owner and github.repo,
+// then the current directory's repository.
+func handleGitHubActionRequest(ctx context.Context, req ghactions.Request, source, question string, debug bool) error {
+	client := ghclient.NewClient(viper.GetString("github.token"), viper.GetString("github.owner"), viper.GetString("github.repo"))
+	repo := ""
+	if req.Repo == "" {
+		owner, name, err := client.ResolveRepository(ctx)
+		if err != nil {
+			return fmt.Errorf("name the repository, e.g. \"in acme/shop\", or set github.owner and github.repo: %w", err)
+		}
+		repo = owner + "/" + name
+	}
+	if debug {
+		fmt.Printf("Delegating query to GitHub Actions agent (%s %s)...\n", req.Op, firstNonEmpty(req.Repo, repo))
+	}
+
+	plan, err := ghactions.NewAgent(client.ExecCLI, debug).Plan(ctx, req, repo, question, time.Now())
+	if err != nil {
+		return err
+	}
+	planJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", plan.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks(source, planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, "github", source, question, plan.Summary, planJSON)
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
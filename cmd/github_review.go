@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	ghclient "github.com/bgdnvk/clanker/internal/github"
+	ghreview "github.com/bgdnvk/clanker/internal/github/review"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var githubReviewCmd = &cobra.Command{
+	Use:   "review <pr-number>",
+	Short: "Summarize a pull request and flag its risky changes",
+	Long: `Review a pull request with the configured AI provider. The diff is fetched
+from GitHub and sent in chunks that fit the model's context; the answers are
+merged into one review with the overall risk, risk areas, missing tests and
+infrastructure, CI, dependency and migration changes.
+
+With --post the review is also added to the pull request as a comment.
+
+Examples:
+  clanker github review 123
+  clanker github review 123 --repo acme/shop --post`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		number, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(args[0]), "#"))
+		if err != nil || number <= 0 {
+			return fmt.Errorf("invalid pull request number %q", args[0])
+		}
+		repo, _ := cmd.Flags().GetString("repo")
+		post, _ := cmd.Flags().GetBool("post")
+		debug, _ := cmd.Flags().GetBool("debug")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		return handleGitHubPRReview(ctx, ghreview.Request{Repo: strings.TrimSpace(repo), Number: number, Post: post}, true, debug)
+	},
+}
+
+func init() {
+	githubCmd.AddCommand(githubReviewCmd)
+	githubReviewCmd.Flags().StringP("repo", "r", "", "Repository as owner/name (default: github.owner/github.repo, or the current directory's repository)")
+	githubReviewCmd.Flags().Bool("post", false, "Post the review as a comment on the pull request")
+	githubReviewCmd.Flags().Bool("debug", false, "Enable debug output")
+}
+
+// shouldRouteToPRReviewAgent reports whether a question asks to summarize
+// or review a numbered pull request
+func shouldRouteToPRReviewAgent(question string) bool {
+	return ghreview.IsReviewQuestion(question)
+}
+
+// handleGitHubPRReviewQuery prints the review of the pull request a
+// question names. Reviews are only posted from clanker github review --post.
+func handleGitHubPRReviewQuery(ctx context.Context, question string, debug bool) error {
+	req, ok := ghreview.ParseRequest(question)
+	if !ok {
+		return fmt.Errorf("name the pull request to review, e.g. \"review PR #123\"")
+	}
+	return handleGitHubPRReview(ctx, req, false, debug)
+}
+
+// handleGitHubPRReview reviews the pull request req names and prints the
+// review, posting it when allowPost and req.Post are both set. Requests that
+// name no repository use github.owner and github.repo, then the current
+// directory's repository.
+func handleGitHubPRReview(ctx context.Context, req ghreview.Request, allowPost, debug bool) error {
+	client := ghclient.NewClient(viper.GetString("github.token"), viper.GetString("github.owner"), viper.GetString("github.repo"))
+	repo := req.Repo
+	if repo == "" {
+		owner, name, err := client.ResolveRepository(ctx)
+		if err != nil {
+			return fmt.Errorf("name the repository, e.g. \"in acme/shop\", or set github.owner and github.repo: %w", err)
+		}
+		repo = owner + "/" + name
+	}
+	if debug {
+		fmt.Printf("Delegating query to PR review agent (%s#%d)...\n", repo, req.Number)
+	}
+
+	aiClient := newConfiguredAIClient(debug)
+	agent := ghreview.NewAgent(client.ExecCLI, aiClient.AskPrompt, aiClient.CleanJSONResponse, debug)
+	review, err := agent.Review(ctx, repo, req.Number)
+	if err != nil {
+		return err
+	}
+	body := review.Format()
+	fmt.Println(body)
+
+	if !req.Post {
+		return nil
+	}
+	if !allowPost {
+		fmt.Printf("// To post this review, run:\n// clanker github review %d --repo %s --post\n", req.Number, repo)
+		return nil
+	}
+	link, err := agent.Post(ctx, repo, req.Number, body)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Posted the review to %s\n", firstNonEmpty(link, review.PR.URL))
+	return nil
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/gitlab"
+	glpipelines "github.com/bgdnvk/clanker/internal/gitlab/pipelines"
+	"github.com/bgdnvk/clanker/internal/planstore"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// gitlabCmd represents the gitlab command
+var gitlabCmd = &cobra.Command{
+	Use:   "gitlab",
+	Short: "Query GitLab project information directly",
+	Long: `Query your GitLab project's pipelines, merge requests and issues without AI
+interpretation, and plan pipeline or job retries. The project comes from
+--project, then gitlab.project, then the current directory's origin remote.`,
+}
+
+var gitlabListCmd = &cobra.Command{
+	Use:   "list [resource]",
+	Short: "List GitLab resources",
+	Long: `List GitLab resources of a specific type.
+
+Supported resources:
+  pipelines    - Recent pipelines
+  mrs          - Open merge requests
+  issues       - Open issues`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project, _ := cmd.Flags().GetString("project")
+		client := newGitLabClient(project)
+		ctx := context.Background()
+
+		switch strings.ToLower(args[0]) {
+		case "pipelines", "pipeline":
+			pipelines, err := client.ListPipelines(ctx, "", "", 20)
+			if err != nil {
+				return err
+			}
+			fmt.Print(gitlab.FormatPipelines(pipelines))
+		case "mrs", "mr", "merge-requests", "mergerequests":
+			mrs, err := client.ListMergeRequests(ctx, 50)
+			if err != nil {
+				return err
+			}
+			fmt.Print(gitlab.FormatMergeRequests(mrs))
+		case "issues", "issue":
+			issues, err := client.ListIssues(ctx, 50)
+			if err != nil {
+				return err
+			}
+			fmt.Print(gitlab.FormatIssues(issues))
+		default:
+			return fmt.Errorf("unsupported resource type: %s", args[0])
+		}
+		return nil
+	},
+}
+
+var gitlabRetryCmd = &cobra.Command{
+	Use:   "retry [pipeline-id]",
+	Short: "Plan retrying a failed pipeline or job",
+	Long: `Plan retrying the failed jobs of a pipeline, or one job with --job. Without a
+pipeline ID the newest pipeline on --ref (default: the default branch) is
+used. Nothing runs until the plan is applied with clanker ask --apply.
+
+Examples:
+  clanker gitlab retry
+  clanker gitlab retry 1002 --project acme/shop
+  clanker gitlab retry --job 77`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var req glpipelines.Request
+		if len(args) == 1 {
+			id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(args[0]), "#"), 10, 64)
+			if err != nil || id <= 0 {
+				return fmt.Errorf("invalid pipeline ID %q", args[0])
+			}
+			req.PipelineID = id
+		}
+		req.JobID, _ = cmd.Flags().GetInt64("job")
+		req.Ref, _ = cmd.Flags().GetString("ref")
+		req.Project, _ = cmd.Flags().GetString("project")
+		debug, _ := cmd.Flags().GetBool("debug")
+
+		question := "retry the latest gitlab pipeline"
+		switch {
+		case req.JobID != 0:
+			question = fmt.Sprintf("retry gitlab job %d", req.JobID)
+		case req.PipelineID != 0:
+			question = fmt.Sprintf("retry gitlab pipeline %d", req.PipelineID)
+		case req.Ref != "":
+			question += " on " + req.Ref
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		return handleGitLabRetryRequest(ctx, req, "gitlab retry", question, debug)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gitlabCmd)
+	gitlabCmd.AddCommand(gitlabListCmd, gitlabRetryCmd)
+	for _, c := range []*cobra.Command{gitlabListCmd, gitlabRetryCmd} {
+		c.Flags().StringP("project", "p", "", "Project as group/name (default: gitlab.project, or the current directory's project)")
+	}
+	gitlabRetryCmd.Flags().Int64("job", 0, "Retry this job instead of a pipeline's failed jobs")
+	gitlabRetryCmd.Flags().String("ref", "", "Without a pipeline ID, use the newest pipeline on this branch")
+	gitlabRetryCmd.Flags().Bool("debug", false, "Enable debug output")
+}
+
+// newGitLabClient creates a client for project, or for gitlab.project when
+// project is empty
+func newGitLabClient(project string) *gitlab.Client {
+	return gitlab.NewClient(viper.GetString("gitlab.token"), viper.GetString("gitlab.host"), firstNonEmpty(project, viper.GetString("gitlab.project")))
+}
+
+var gitlabQuestionRe = regexp.MustCompile(`(?i)\bgitlab\b|\bmerge requests?\b|/-/(?:pipelines|jobs|merge_requests)/`)
+
+// useGitLabContext reports whether repository context for question should
+// come from GitLab instead of GitHub: the question names GitLab, only
+// gitlab.project is configured, or, with neither configured, the current
+// directory's origin remote is hosted on GitLab
+func useGitLabContext(ctx context.Context, question string) bool {
+	if gitlabQuestionRe.MatchString(question) {
+		return true
+	}
+	githubConfigured := viper.GetString("github.repo") != "" || len(viper.GetStringSlice("github.repos")) > 0
+	if githubConfigured {
+		return false
+	}
+	if viper.GetString("gitlab.project") != "" {
+		return true
+	}
+	_, _, ok := gitlab.DetectRemote(ctx)
+	return ok
+}
+
+// shouldRouteToGitLabCIAgent reports whether a question asks to retry a
+// GitLab pipeline or job
+func shouldRouteToGitLabCIAgent(question string) bool {
+	return glpipelines.IsRetryQuestion(question)
+}
+
+// handleGitLabCIQuery prints the plan for the retry a question asks for.
+// Plans are never applied here.
+func handleGitLabCIQuery(ctx context.Context, question string, debug bool) error {
+	return handleGitLabRetryRequest(ctx, glpipelines.ParseRequest(question), "ask gitlab-ci", question, debug)
+}
+
+// handleGitLabRetryRequest confirms req against GitLab and prints its plan
+func handleGitLabRetryRequest(ctx context.Context, req glpipelines.Request, source, question string, debug bool) error {
+	client := newGitLabClient(req.Project)
+	if debug {
+		fmt.Printf("Delegating query to GitLab CI agent (%s)...\n", firstNonEmpty(req.Project, viper.GetString("gitlab.project"), "current project"))
+	}
+
+	plan, err := glpipelines.Plan(ctx, client, req, question, time.Now())
+	if err != nil {
+		return err
+	}
+	planJSON, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", plan.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks(source, planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, "gitlab", source, question, plan.Summary, planJSON)
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bgdnvk/clanker/internal/hooks"
+	"github.com/bgdnvk/clanker/internal/k8s/cluster"
+	"github.com/bgdnvk/clanker/internal/notify"
+)
+
+// runPlanGeneratedHooks fires plan_generated hooks for a plan that was just
+// printed. A failing hook fails the command so local policy checks can gate
+// scripts and CI on clanker's exit status.
+func runPlanGeneratedHooks(source string, planJSON []byte) error {
+	if err := hooks.Run(context.Background(), hooks.EventPlanGenerated, source, planJSON); err != nil {
+		return fmt.Errorf("plan rejected by hook: %w", err)
+	}
+	return nil
+}
+
+// runPlanAppliedHooks fires plan_applied hooks once an apply finishes. The
+// apply already happened, so hook failures are only reported.
+func runPlanAppliedHooks(source string, planJSON []byte, applyErr error) {
+	if err := hooks.RunApplied(context.Background(), source, planJSON, applyErr); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
+
+// notifyPlanApplied tells the notify sinks an apply finished, as
+// plan_applied or apply_failed; failures are only reported
+func notifyPlanApplied(source string, planJSON []byte, applyErr error) {
+	if err := notify.Send(context.Background(), notify.PlanApplied(source, planJSON, applyErr)); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
+
+// notifyClusterCreated tells the notify sinks a cluster is up; failures are
+// only reported
+func notifyClusterCreated(provider string, info *cluster.ClusterInfo, kubeconfig string) {
+	if err := notify.Send(context.Background(), notify.ClusterCreated(provider, info, kubeconfig)); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
+
+// runReportHooks fires report_generated hooks; failures are only reported
+func runReportHooks(source string, report interface{}) {
+	if err := hooks.Run(context.Background(), hooks.EventReportGenerated, source, report); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
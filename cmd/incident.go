@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/aws"
+	ghclient "github.com/bgdnvk/clanker/internal/github"
+	"github.com/bgdnvk/clanker/internal/incident"
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/bgdnvk/clanker/internal/sentry"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	incidentNamespace string
+	incidentSince     time.Duration
+	incidentRepo      string
+	incidentZones     []string
+	incidentProfile   string
+	incidentDir       string
+)
+
+var incidentCmd = &cobra.Command{
+	Use:   "incident",
+	Short: "Gather cross-provider evidence for an incident",
+}
+
+var incidentStartCmd = &cobra.Command{
+	Use:   "start <description>",
+	Short: "Collect evidence from every configured provider and ask the AI what connects it",
+	Long: `Start an incident and gather its evidence in parallel:
+
+  • the Kubernetes SRE diagnosis of the service's namespace
+  • Helm releases and GitHub deployments and deploy workflow runs
+  • Cloudflare zones whose 5xx rate or traffic moved against the day before
+  • unresolved Sentry issues seen in the window, new ones marked
+  • ALB 5xx counts from CloudWatch
+
+The service is the first meaningful word of the description ("checkout" in
+"checkout is down"); its namespace is found from the cluster unless
+--namespace is given. Sources that are not configured are skipped and
+sources that fail are listed, so the rest still report. The AI then reads
+the evidence and proposes the most likely cause.
+
+Everything is written to ~/.clanker/incidents/<id>/ as incident.json and
+incident.md.
+
+Examples:
+  clanker incident start "checkout is down"
+  clanker incident start "payments returning 502s" --namespace payments --since 1h
+  clanker incident start "api errors" --repo acme/api --zone example.com --profile prod`,
+	Args: cobra.ExactArgs(1),
+	RunE: runIncidentStart,
+}
+
+func init() {
+	rootCmd.AddCommand(incidentCmd)
+	incidentCmd.AddCommand(incidentStartCmd)
+
+	incidentStartCmd.Flags().StringVarP(&incidentNamespace, "namespace", "n", "", "Kubernetes namespace of the service (default: found from the description)")
+	incidentStartCmd.Flags().DurationVar(&incidentSince, "since", incident.DefaultWindow, "How far back to gather evidence")
+	incidentStartCmd.Flags().StringVar(&incidentRepo, "repo", "", "GitHub repository (owner/name) to read deploys from (default: github.owner and github.repo, then the current directory)")
+	incidentStartCmd.Flags().StringSliceVar(&incidentZones, "zone", nil, "Cloudflare zones to check (repeatable; default: every zone)")
+	incidentStartCmd.Flags().StringVar(&incidentProfile, "profile", "", "AWS profile for the ALB metrics")
+	incidentStartCmd.Flags().StringVar(&incidentDir, "dir", "", "Directory to write the incident bundle under (default: ~/.clanker/incidents)")
+}
+
+func runIncidentStart(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	debug := viper.GetBool("debug")
+	title := strings.TrimSpace(args[0])
+	if title == "" {
+		return fmt.Errorf("describe the incident, e.g. clanker incident start \"checkout is down\"")
+	}
+
+	dir := incidentDir
+	if dir == "" {
+		var err error
+		if dir, err = incident.Dir(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Gathering evidence for %q over the last %s...\n", title, incidentSince)
+	bundle := incident.NewAgent(incidentSources(ctx, debug), debug).Start(ctx, incident.Options{
+		Title:     title,
+		Namespace: incidentNamespace,
+		Window:    incidentSince,
+		Now:       time.Now(),
+	})
+	fmt.Print(bundle.Format())
+
+	path, err := bundle.Write(dir)
+	if err != nil {
+		return err
+	}
+	runReportHooks("incident start", bundle)
+	fmt.Fprintf(os.Stderr, "\nIncident bundle written to %s\n", path)
+	return nil
+}
+
+// incidentSources reaches the cluster through the configured kubeconfig,
+// GitHub through gh, Cloudflare and Sentry through their configured tokens
+// and AWS through the profile. A source that cannot be reached is left out.
+func incidentSources(ctx context.Context, debug bool) incident.Sources {
+	var sources incident.Sources
+
+	k8sClient := k8s.NewClient(viper.GetString("kubernetes.kubeconfig"), "", debug)
+	sources.Kubectl = func(ctx context.Context, args []string) (string, error) {
+		return k8sClient.Run(ctx, args...)
+	}
+	sources.Helm = func(ctx context.Context, args []string) (string, error) {
+		return k8sClient.RunHelm(ctx, args...)
+	}
+	diagnostics := sre.NewDiagnosticsManager(k8sClient, debug)
+	sources.Diagnose = func(ctx context.Context, namespace string) (*sre.DiagnosticReport, error) {
+		if namespace == "" {
+			return diagnostics.DiagnoseCluster(ctx)
+		}
+		return diagnostics.DiagnoseNamespace(ctx, namespace)
+	}
+
+	ghClient := ghclient.NewClient(viper.GetString("github.token"), viper.GetString("github.owner"), viper.GetString("github.repo"))
+	sources.GitHubRepo = incidentRepo
+	if sources.GitHubRepo == "" {
+		if owner, name, err := ghClient.ResolveRepository(ctx); err == nil {
+			sources.GitHubRepo = owner + "/" + name
+		} else if debug {
+			fmt.Printf("[incident] GitHub repository unknown: %v\n", err)
+		}
+	}
+	sources.GitHub = ghClient.ExecCLI
+
+	if cfClient, err := newCloudflareAgentClient(ctx, debug); err == nil {
+		sources.Cloudflare = func(ctx context.Context, args []string) (string, error) {
+			body := ""
+			if len(args) > 2 {
+				body = args[2]
+			}
+			return cfClient.RunAPIWithContext(ctx, args[0], args[1], body)
+		}
+		sources.Zones = incidentZones
+	} else if debug {
+		fmt.Printf("[incident] Cloudflare unavailable: %v\n", err)
+	}
+
+	if token, org := sentry.ResolveAuthToken(), sentry.ResolveOrgSlug(); token != "" && org != "" {
+		if client, err := sentry.NewClient(token, org, sentry.ResolveHost(), debug); err == nil {
+			sources.Sentry, sources.SentryOrg = client, org
+		} else if debug {
+			fmt.Printf("[incident] Sentry unavailable: %v\n", err)
+		}
+	}
+
+	targetProfile := resolveAWSProfile(incidentProfile)
+	if awsClient, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug); err == nil {
+		sources.AWS = awsClient.ExecCLI
+	} else if debug {
+		fmt.Printf("[incident] AWS profile %s unavailable: %v\n", targetProfile, err)
+	}
+
+	aiClient := newConfiguredAIClient(debug)
+	sources.Ask = aiClient.AskPrompt
+	return sources
+}
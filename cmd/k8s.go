@@ -15,7 +15,9 @@ import (
 	"github.com/bgdnvk/clanker/internal/ai"
 	"github.com/bgdnvk/clanker/internal/azure"
 	"github.com/bgdnvk/clanker/internal/gcp"
+	"github.com/bgdnvk/clanker/internal/hetzner"
 	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/backup"
 	"github.com/bgdnvk/clanker/internal/k8s/cluster"
 	"github.com/bgdnvk/clanker/internal/k8s/plan"
 	"github.com/spf13/cobra"
@@ -49,12 +51,17 @@ Example:
 
 var k8sCreateKubeadmCmd = &cobra.Command{
 	Use:   "kubeadm [cluster-name]",
-	Short: "Create a kubeadm cluster on EC2",
-	Long: `Create a new kubeadm-based Kubernetes cluster on EC2 instances.
+	Short: "Create a kubeadm cluster on EC2 or Hetzner Cloud",
+	Long: `Create a new kubeadm-based Kubernetes cluster on EC2 instances or
+Hetzner Cloud servers.
 
 Example:
   clanker k8s create kubeadm my-cluster --workers 1 --key-pair my-key
-  clanker k8s create kubeadm my-cluster --plan  # Show plan only`,
+  clanker k8s create kubeadm my-cluster --plan  # Show plan only
+  clanker k8s create kubeadm my-cluster --resume  # Continue after a failure
+  clanker k8s create kubeadm my-cluster --control-planes 3  # HA control plane
+  clanker k8s create kubeadm my-cluster --provider hetzner --location nbg1 \
+      --key-pair my-hcloud-key --ssh-key ~/.ssh/id_ed25519`,
 	Args: cobra.ExactArgs(1),
 	RunE: runCreateKubeadm,
 }
@@ -66,7 +73,9 @@ var k8sCreateGKECmd = &cobra.Command{
 
 Example:
   clanker k8s create gke my-cluster --gcp-project my-project --nodes 2 --node-type e2-standard-2
-  clanker k8s create gke my-cluster --gcp-project my-project --gcp-region us-central1 --plan`,
+  clanker k8s create gke my-cluster --gcp-project my-project --gcp-region us-central1 --plan
+  clanker k8s create gke my-cluster --gcp-project my-project --autopilot --release-channel stable
+  clanker k8s create gke my-cluster --gcp-project my-project --enable-autoprovisioning --max-cpu 64 --max-memory 256`,
 	Args: cobra.ExactArgs(1),
 	RunE: runCreateGKE,
 }
@@ -95,11 +104,52 @@ Example:
   clanker k8s delete eks my-cluster
   clanker k8s delete gke my-cluster --gcp-project my-project
   clanker k8s delete aks my-cluster --azure-resource-group my-rg
-  clanker k8s delete kubeadm my-cluster`,
+  clanker k8s delete kubeadm my-cluster
+  clanker k8s delete kubeadm my-cluster --provider hetzner
+  clanker k8s delete eks my-cluster --dry-run  # List what would be removed`,
 	Args: cobra.ExactArgs(2),
 	RunE: runDeleteCluster,
 }
 
+var k8sUpgradeCmd = &cobra.Command{
+	Use:   "upgrade [cluster-type] [cluster-name]",
+	Short: "Upgrade a cluster to a new Kubernetes version",
+	Long: `Upgrade a cluster's control plane and then its nodes to the next
+Kubernetes minor version. Node groups already on the target version are
+skipped, so a failed upgrade can be rerun. When the current kubectl
+context is the cluster and Velero is installed in it, every namespace is
+backed up first.
+
+Example:
+  clanker k8s upgrade eks my-cluster --version 1.30
+  clanker k8s upgrade gke my-cluster --version 1.30 --gcp-project my-project
+  clanker k8s upgrade aks my-cluster --version 1.30 --azure-resource-group my-rg
+  clanker k8s upgrade kubeadm my-cluster --version 1.30 --ssh-key ~/.ssh/my-key
+  clanker k8s upgrade eks my-cluster --version 1.30 --plan  # Show plan only`,
+	Args: cobra.ExactArgs(2),
+	RunE: runUpgradeCluster,
+}
+
+var k8sPatchCmd = &cobra.Command{
+	Use:   "patch [cluster-type] [cluster-name]",
+	Short: "Patch node operating systems and reboot nodes one at a time",
+	Long: `Bring every node's operating system up to date without changing the
+Kubernetes version. Nodes are cordoned, drained, patched and uncordoned one
+at a time, and the next node only starts once evicted pods are running again.
+
+kubeadm nodes get apt or yum updates over SSH and are rebooted. EKS, GKE and
+AKS node groups are rolled onto the latest node image for their version.
+
+Example:
+  clanker k8s patch kubeadm my-cluster --ssh-key ~/.ssh/my-key
+  clanker k8s patch eks my-cluster
+  clanker k8s patch gke my-cluster --gcp-project my-project
+  clanker k8s patch aks my-cluster --azure-resource-group my-rg
+  clanker k8s patch kubeadm my-cluster --plan  # Show plan only`,
+	Args: cobra.ExactArgs(2),
+	RunE: runPatchNodes,
+}
+
 var k8sListCmd = &cobra.Command{
 	Use:   "list [cluster-type]",
 	Short: "List Kubernetes clusters",
@@ -112,7 +162,8 @@ Example:
   clanker k8s list eks
   clanker k8s list gke --gcp-project my-project
 	clanker k8s list aks --azure-subscription <subscription-id>
-  clanker k8s list kubeadm`,
+  clanker k8s list kubeadm
+  clanker k8s list kubeadm --provider hetzner`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runListClusters,
 }
@@ -139,7 +190,8 @@ Example:
   clanker k8s kubeconfig eks my-cluster
   clanker k8s kubeconfig gke my-cluster --gcp-project my-project
 	clanker k8s kubeconfig aks my-cluster --azure-subscription <subscription-id> --azure-resource-group <resource-group>
-  clanker k8s kubeconfig kubeadm my-cluster`,
+  clanker k8s kubeconfig kubeadm my-cluster
+  clanker k8s kubeconfig kubeadm my-cluster --provider hetzner --ssh-key ~/.ssh/id_ed25519`,
 	Args: cobra.ExactArgs(2),
 	RunE: runGetKubeconfig,
 }
@@ -254,6 +306,8 @@ var (
 	k8sWorkers      int
 	k8sKeyPair      string
 	k8sSSHKeyPath   string
+	k8sSSHUser      string
+	k8sJumpHost     string
 	k8sK8sVersion   string
 	k8sPlanOnly     bool
 	k8sApply        bool
@@ -263,6 +317,10 @@ var (
 	k8sNamespace    string
 	k8sClusterName  string
 	k8sOutputFormat string
+	// List flags for large clusters
+	k8sSelector         string
+	k8sFieldSelector    string
+	k8sSummaryThreshold int
 	// Logs flags
 	k8sLogContainer     string
 	k8sLogFollow        bool
@@ -288,10 +346,25 @@ var (
 	k8sGCPProject     string
 	k8sGCPRegion      string
 	k8sGKEPreemptible bool
+	k8sGKEAutopilot   bool
+	k8sGKEChannel     string
+	k8sGKEAutoProv    bool
+	k8sGKEMaxCPU      int
+	k8sGKEMaxMemoryGB int
 	// AKS flags
 	k8sAzureSubscription  string
 	k8sAzureResourceGroup string
 	k8sAzureRegion        string
+	// kubeadm flags
+	k8sKubeadmProvider string
+	k8sHetznerLocation string
+	k8sResume          bool
+	k8sControlPlanes   int
+	// Upgrade flags
+	k8sUpgradeVersion string
+	k8sSkipBackup     bool
+	// Delete flags
+	k8sDeleteDryRun bool
 )
 
 func init() {
@@ -300,6 +373,8 @@ func init() {
 	// Add subcommands
 	k8sCmd.AddCommand(k8sCreateCmd)
 	k8sCmd.AddCommand(k8sDeleteCmd)
+	k8sCmd.AddCommand(k8sUpgradeCmd)
+	k8sCmd.AddCommand(k8sPatchCmd)
 	k8sCmd.AddCommand(k8sListCmd)
 	k8sCmd.AddCommand(k8sDeployCmd)
 	k8sCmd.AddCommand(k8sGetKubeconfigCmd)
@@ -319,12 +394,18 @@ func init() {
 
 	// Kubeadm create flags
 	k8sCreateKubeadmCmd.Flags().IntVar(&k8sWorkers, "workers", 1, "Number of worker nodes")
+	k8sCreateKubeadmCmd.Flags().IntVar(&k8sControlPlanes, "control-planes", 1, "Number of control plane nodes (3 or 5 for HA behind an HAProxy node)")
 	k8sCreateKubeadmCmd.Flags().StringVar(&k8sNodeType, "node-type", "t3.small", "EC2 instance type for nodes")
 	k8sCreateKubeadmCmd.Flags().StringVar(&k8sKeyPair, "key-pair", "", "AWS key pair name for SSH access (auto-creates if not exists)")
 	k8sCreateKubeadmCmd.Flags().StringVar(&k8sSSHKeyPath, "ssh-key", "", "Path to SSH private key (default: ~/.ssh/<key-pair>)")
+	k8sCreateKubeadmCmd.Flags().StringVar(&k8sSSHUser, "ssh-user", "", "SSH login user for kubeadm nodes (default: ubuntu on AWS, root on Hetzner)")
+	k8sCreateKubeadmCmd.Flags().StringVar(&k8sJumpHost, "jump-host", "", "Bastion for kubeadm nodes in private subnets, as [user@]host[:port]")
 	k8sCreateKubeadmCmd.Flags().StringVar(&k8sK8sVersion, "version", "1.29", "Kubernetes version")
 	k8sCreateKubeadmCmd.Flags().BoolVar(&k8sPlanOnly, "plan", false, "Show plan without applying")
 	k8sCreateKubeadmCmd.Flags().BoolVar(&k8sApply, "apply", false, "Apply the plan (default prompts for confirmation)")
+	k8sCreateKubeadmCmd.Flags().StringVar(&k8sKubeadmProvider, "provider", "aws", "Where to run the nodes (aws or hetzner)")
+	k8sCreateKubeadmCmd.Flags().StringVar(&k8sHetznerLocation, "location", "", "Hetzner location for --provider hetzner (default: fsn1)")
+	k8sCreateKubeadmCmd.Flags().BoolVar(&k8sResume, "resume", false, "Continue an interrupted creation from its last completed step")
 
 	// GKE create flags
 	k8sCreateGKECmd.Flags().StringVar(&k8sGCPProject, "gcp-project", "", "GCP project ID (required)")
@@ -333,6 +414,11 @@ func init() {
 	k8sCreateGKECmd.Flags().StringVar(&k8sNodeType, "node-type", "e2-standard-2", "GCE machine type for nodes")
 	k8sCreateGKECmd.Flags().StringVar(&k8sK8sVersion, "version", "", "Kubernetes version (default: GKE default)")
 	k8sCreateGKECmd.Flags().BoolVar(&k8sGKEPreemptible, "preemptible", false, "Use preemptible VMs for nodes")
+	k8sCreateGKECmd.Flags().BoolVar(&k8sGKEAutopilot, "autopilot", false, "Create an Autopilot cluster, where GKE manages the nodes (--nodes and --node-type are ignored)")
+	k8sCreateGKECmd.Flags().StringVar(&k8sGKEChannel, "release-channel", "", "Release channel: rapid, regular, stable, extended or none (default: GKE default)")
+	k8sCreateGKECmd.Flags().BoolVar(&k8sGKEAutoProv, "enable-autoprovisioning", false, "Let GKE create node pools for pending pods, within --max-cpu and --max-memory")
+	k8sCreateGKECmd.Flags().IntVar(&k8sGKEMaxCPU, "max-cpu", 0, "Most vCPUs node auto-provisioning may run in the cluster")
+	k8sCreateGKECmd.Flags().IntVar(&k8sGKEMaxMemoryGB, "max-memory", 0, "Most memory in GB node auto-provisioning may run in the cluster")
 	k8sCreateGKECmd.Flags().BoolVar(&k8sPlanOnly, "plan", false, "Show plan without applying")
 	k8sCreateGKECmd.Flags().BoolVar(&k8sApply, "apply", false, "Apply the plan (default prompts for confirmation)")
 	k8sCreateGKECmd.MarkFlagRequired("gcp-project")
@@ -348,6 +434,33 @@ func init() {
 	k8sCreateAKSCmd.Flags().BoolVar(&k8sApply, "apply", false, "Apply the plan (default prompts for confirmation)")
 	k8sCreateAKSCmd.MarkFlagRequired("azure-resource-group")
 
+	// Upgrade flags
+	k8sUpgradeCmd.Flags().StringVar(&k8sUpgradeVersion, "version", "", "Target Kubernetes minor version, e.g. 1.30 (required)")
+	k8sUpgradeCmd.Flags().BoolVar(&k8sPlanOnly, "plan", false, "Show plan without applying")
+	k8sUpgradeCmd.Flags().BoolVar(&k8sApply, "apply", false, "Apply the plan (default prompts for confirmation)")
+	k8sUpgradeCmd.Flags().StringVar(&k8sGCPProject, "gcp-project", "", "GCP project ID for GKE clusters")
+	k8sUpgradeCmd.Flags().StringVar(&k8sGCPRegion, "gcp-region", "", "GCP region for GKE clusters")
+	k8sUpgradeCmd.Flags().StringVar(&k8sAzureSubscription, "azure-subscription", "", "Azure subscription ID for AKS clusters")
+	k8sUpgradeCmd.Flags().StringVar(&k8sAzureResourceGroup, "azure-resource-group", "", "Azure resource group for AKS clusters (required for AKS)")
+	k8sUpgradeCmd.Flags().StringVar(&k8sKubeadmProvider, "provider", "aws", "Where kubeadm cluster nodes run (aws or hetzner)")
+	k8sUpgradeCmd.Flags().StringVar(&k8sSSHKeyPath, "ssh-key", "", "Path to SSH private key for kubeadm clusters")
+	k8sUpgradeCmd.Flags().StringVar(&k8sSSHUser, "ssh-user", "", "SSH login user for kubeadm nodes (default: ubuntu on AWS, root on Hetzner)")
+	k8sUpgradeCmd.Flags().StringVar(&k8sJumpHost, "jump-host", "", "Bastion for kubeadm nodes in private subnets, as [user@]host[:port]")
+	k8sUpgradeCmd.Flags().BoolVar(&k8sSkipBackup, "skip-backup", false, "Upgrade without the Velero backup taken first when Velero is installed")
+	k8sUpgradeCmd.MarkFlagRequired("version")
+
+	// Patch command flags
+	k8sPatchCmd.Flags().BoolVar(&k8sPlanOnly, "plan", false, "Show plan without applying")
+	k8sPatchCmd.Flags().BoolVar(&k8sApply, "apply", false, "Apply the plan (default prompts for confirmation)")
+	k8sPatchCmd.Flags().StringVar(&k8sGCPProject, "gcp-project", "", "GCP project ID for GKE clusters")
+	k8sPatchCmd.Flags().StringVar(&k8sGCPRegion, "gcp-region", "", "GCP region for GKE clusters")
+	k8sPatchCmd.Flags().StringVar(&k8sAzureSubscription, "azure-subscription", "", "Azure subscription ID for AKS clusters")
+	k8sPatchCmd.Flags().StringVar(&k8sAzureResourceGroup, "azure-resource-group", "", "Azure resource group for AKS clusters (required for AKS)")
+	k8sPatchCmd.Flags().StringVar(&k8sKubeadmProvider, "provider", "aws", "Where kubeadm cluster nodes run (aws or hetzner)")
+	k8sPatchCmd.Flags().StringVar(&k8sSSHKeyPath, "ssh-key", "", "Path to SSH private key for kubeadm clusters")
+	k8sPatchCmd.Flags().StringVar(&k8sSSHUser, "ssh-user", "", "SSH login user for kubeadm nodes (default: ubuntu on AWS, root on Hetzner)")
+	k8sPatchCmd.Flags().StringVar(&k8sJumpHost, "jump-host", "", "Bastion for kubeadm nodes in private subnets, as [user@]host[:port]")
+
 	// Deploy flags
 	k8sDeployCmd.Flags().StringVar(&k8sDeployName, "name", "", "Deployment name (default: image name)")
 	k8sDeployCmd.Flags().IntVar(&k8sDeployPort, "port", 80, "Container port to expose")
@@ -359,6 +472,7 @@ func init() {
 	// Resources flags
 	k8sResourcesCmd.Flags().StringVar(&k8sClusterName, "cluster", "", "Cluster name (optional, uses current context if not specified)")
 	k8sResourcesCmd.Flags().StringVarP(&k8sOutputFormat, "output", "o", "json", "Output format (json or yaml)")
+	addK8sListFlags(k8sResourcesCmd)
 
 	// Add logs and stats commands
 	k8sCmd.AddCommand(k8sLogsCmd)
@@ -405,6 +519,7 @@ func init() {
 	k8sAskCmd.Flags().StringVar(&k8sAskKubeconfig, "kubeconfig", "", "Path to kubeconfig file (default: ~/.kube/config)")
 	k8sAskCmd.Flags().StringVar(&k8sAskContext, "context", "", "kubectl context to use (overrides --cluster)")
 	k8sAskCmd.Flags().StringVarP(&k8sNamespace, "namespace", "n", "", "Default namespace for queries (default: all namespaces)")
+	addK8sListFlags(k8sAskCmd)
 	k8sAskCmd.Flags().StringVar(&k8sAskAIProfile, "ai-profile", "", "AI profile to use for LLM queries")
 	k8sAskCmd.Flags().StringVar(&k8sAskModel, "model", "", "AI model to use for LLM queries (overrides selected AI profile config)")
 	k8sAskCmd.Flags().BoolVar(&k8sAskDebug, "debug", false, "Enable debug output")
@@ -420,14 +535,21 @@ func init() {
 	k8sDeleteCmd.Flags().StringVar(&k8sAzureSubscription, "azure-subscription", "", "Azure subscription ID for AKS clusters")
 	k8sDeleteCmd.Flags().StringVar(&k8sAzureResourceGroup, "azure-resource-group", "", "Azure resource group for AKS clusters (required for AKS)")
 	k8sDeleteCmd.Flags().StringVar(&k8sAzureRegion, "azure-region", "", "Azure region for AKS clusters")
+	k8sDeleteCmd.Flags().StringVar(&k8sKubeadmProvider, "provider", "aws", "Where kubeadm cluster nodes run (aws or hetzner)")
+	k8sDeleteCmd.Flags().BoolVar(&k8sDeleteDryRun, "dry-run", false, "List what deleting the cluster would remove and leave behind, without deleting (eks and kubeadm)")
 	k8sGetKubeconfigCmd.Flags().StringVar(&k8sGCPProject, "gcp-project", "", "GCP project ID for GKE clusters")
 	k8sGetKubeconfigCmd.Flags().StringVar(&k8sGCPRegion, "gcp-region", "", "GCP region for GKE clusters")
 	k8sListCmd.Flags().StringVar(&k8sAzureSubscription, "azure-subscription", "", "Azure subscription ID for AKS clusters")
 	k8sListCmd.Flags().StringVar(&k8sAzureResourceGroup, "azure-resource-group", "", "Azure resource group for AKS clusters")
 	k8sListCmd.Flags().StringVar(&k8sAzureRegion, "azure-region", "", "Azure region for AKS clusters")
+	k8sListCmd.Flags().StringVar(&k8sKubeadmProvider, "provider", "aws", "Where kubeadm cluster nodes run (aws or hetzner)")
 	k8sGetKubeconfigCmd.Flags().StringVar(&k8sAzureSubscription, "azure-subscription", "", "Azure subscription ID for AKS clusters")
 	k8sGetKubeconfigCmd.Flags().StringVar(&k8sAzureResourceGroup, "azure-resource-group", "", "Azure resource group for AKS clusters")
 	k8sGetKubeconfigCmd.Flags().StringVar(&k8sAzureRegion, "azure-region", "", "Azure region for AKS clusters")
+	k8sGetKubeconfigCmd.Flags().StringVar(&k8sKubeadmProvider, "provider", "aws", "Where kubeadm cluster nodes run (aws or hetzner)")
+	k8sGetKubeconfigCmd.Flags().StringVar(&k8sSSHKeyPath, "ssh-key", "", "Path to SSH private key for kubeadm clusters")
+	k8sGetKubeconfigCmd.Flags().StringVar(&k8sSSHUser, "ssh-user", "", "SSH login user for kubeadm nodes (default: ubuntu on AWS, root on Hetzner)")
+	k8sGetKubeconfigCmd.Flags().StringVar(&k8sJumpHost, "jump-host", "", "Bastion for kubeadm nodes in private subnets, as [user@]host[:port]")
 	k8sResourcesCmd.Flags().StringVar(&k8sAzureSubscription, "azure-subscription", "", "Azure subscription ID for AKS clusters")
 	k8sResourcesCmd.Flags().StringVar(&k8sAzureResourceGroup, "azure-resource-group", "", "Azure resource group for AKS clusters")
 	k8sResourcesCmd.Flags().StringVar(&k8sAzureRegion, "azure-region", "", "Azure region for AKS clusters")
@@ -599,6 +721,65 @@ func commandExists(name string) bool {
 	return err == nil
 }
 
+// isHetznerKubeadm reports whether --provider selects Hetzner Cloud for
+// kubeadm nodes
+func isHetznerKubeadm() bool {
+	return strings.EqualFold(strings.TrimSpace(k8sKubeadmProvider), "hetzner")
+}
+
+// kubeadmProviderOptions builds kubeadm provider options for --provider: the
+// default EC2 backend from the AWS settings, or a Hetzner Cloud backend
+// using hetzner.api_token / HCLOUD_TOKEN
+func kubeadmProviderOptions(awsProfile, awsRegion, keyName, sshKeyPath string) (k8s.KubeadmProviderOptions, error) {
+	switch strings.ToLower(strings.TrimSpace(k8sKubeadmProvider)) {
+	case "", "aws", "ec2":
+		return k8s.KubeadmProviderOptions{
+			AWSProfile:  awsProfile,
+			Region:      awsRegion,
+			KeyPairName: keyName,
+			SSHKeyPath:  sshKeyPath,
+			SSHUser:     kubeadmSSHUser(),
+			JumpHost:    kubeadmJumpHost(),
+		}, nil
+	case "hetzner":
+		debug := viper.GetBool("debug")
+		client, err := hetzner.NewClient(hetzner.ResolveAPIToken(), debug)
+		if err != nil {
+			return k8s.KubeadmProviderOptions{}, fmt.Errorf("%w (set hetzner.api_token or HCLOUD_TOKEN)", err)
+		}
+		return k8s.KubeadmProviderOptions{
+			SSHKeyPath: sshKeyPath,
+			SSHUser:    kubeadmSSHUser(),
+			JumpHost:   kubeadmJumpHost(),
+			Backend: cluster.NewHetznerBackend(cluster.HetznerBackendOptions{
+				Client:     client,
+				Location:   k8sHetznerLocation,
+				SSHKeyName: keyName,
+				Debug:      debug,
+			}),
+		}, nil
+	default:
+		return k8s.KubeadmProviderOptions{}, fmt.Errorf("unsupported kubeadm provider: %s (use 'aws' or 'hetzner')", k8sKubeadmProvider)
+	}
+}
+
+// kubeadmSSHUser returns --ssh-user or kubernetes.kubeadm.ssh_user; empty
+// keeps the backend's default user
+func kubeadmSSHUser() string {
+	if k8sSSHUser != "" {
+		return k8sSSHUser
+	}
+	return strings.TrimSpace(viper.GetString("kubernetes.kubeadm.ssh_user"))
+}
+
+// kubeadmJumpHost returns --jump-host or kubernetes.kubeadm.jump_host
+func kubeadmJumpHost() string {
+	if k8sJumpHost != "" {
+		return k8sJumpHost
+	}
+	return strings.TrimSpace(viper.GetString("kubernetes.kubeadm.jump_host"))
+}
+
 func hasAWSProviderSignals() bool {
 	defaultEnv := viper.GetString("infra.default_environment")
 	if defaultEnv == "" {
@@ -722,9 +903,32 @@ func getResourcesFromContext(ctx context.Context, clusterName, kubeconfigPath, k
 	return agent.GetClusterResources(ctx, clusterName, k8s.QueryOptions{
 		ClusterName: clusterName,
 		Kubeconfig:  kubeconfigPath,
+		Context:     kubeContext,
+		ListOptions: k8sListOptions(),
 	})
 }
 
+// addK8sListFlags adds the selector and summary flags for large clusters
+func addK8sListFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&k8sSelector, "selector", "l", "", "Label selector pushed down into list calls, e.g. app=web,tier!=cache")
+	cmd.Flags().StringVar(&k8sFieldSelector, "field-selector", "", "Field selector pushed down into pod list calls, e.g. status.phase=Pending")
+	cmd.Flags().IntVar(&k8sSummaryThreshold, "summary-threshold", 0, "Count pods per namespace and phase instead of listing them above this many (default kubernetes.summary_threshold or 2000, -1 always lists)")
+}
+
+// k8sListOptions returns the list flags, with kubernetes.summary_threshold
+// as the default threshold
+func k8sListOptions() k8s.ListOptions {
+	threshold := k8sSummaryThreshold
+	if threshold == 0 {
+		threshold = viper.GetInt("kubernetes.summary_threshold")
+	}
+	return k8s.ListOptions{
+		LabelSelector:    k8sSelector,
+		FieldSelector:    k8sFieldSelector,
+		SummaryThreshold: threshold,
+	}
+}
+
 func findExistingClusterContext(ctx context.Context, providerCtx *multiProviderK8sContext, clusterName string) (string, bool, error) {
 	provider, ok := providerCtx.agent.GetClusterProvider(k8s.ClusterTypeExisting)
 	if !ok {
@@ -853,6 +1057,7 @@ func summarizeProviderErrors(errs map[k8s.ClusterType]error) string {
 func hasClusterResourceData(resources *k8s.ClusterResources) bool {
 	return len(resources.Nodes) > 0 ||
 		len(resources.Pods) > 0 ||
+		resources.PodSummary != nil ||
 		len(resources.Services) > 0 ||
 		len(resources.PVs) > 0 ||
 		len(resources.PVCs) > 0 ||
@@ -1006,7 +1211,7 @@ func runCreateEKS(cmd *cobra.Command, args []string) error {
 		fmt.Println("Plan JSON:")
 		planJSON, _ := json.MarshalIndent(k8sPlan, "", "  ")
 		fmt.Println(string(planJSON))
-		return nil
+		return runPlanGeneratedHooks("k8s", planJSON)
 	}
 
 	// Confirm unless --apply
@@ -1061,6 +1266,7 @@ func runCreateEKS(cmd *cobra.Command, args []string) error {
 
 	// Display result
 	plan.DisplayResult(os.Stdout, k8sPlan, result)
+	notifyClusterCreated("EKS", info, result.Connection.Kubeconfig)
 
 	return nil
 }
@@ -1070,6 +1276,10 @@ func runCreateKubeadm(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	debug := viper.GetBool("debug")
 
+	if isHetznerKubeadm() {
+		return runCreateKubeadmHetzner(cmd, clusterName)
+	}
+
 	_, awsProfile, awsRegion := getK8sAgent()
 
 	// Default key pair name if not provided
@@ -1090,17 +1300,33 @@ func runCreateKubeadm(cmd *cobra.Command, args []string) error {
 		sshKeyPath = sshKeyInfo.PrivateKeyPath
 	}
 
+	providerOpts := k8s.KubeadmProviderOptions{
+		AWSProfile:  awsProfile,
+		Region:      awsRegion,
+		KeyPairName: keyPairName,
+		SSHKeyPath:  sshKeyPath,
+		SSHUser:     kubeadmSSHUser(),
+		JumpHost:    kubeadmJumpHost(),
+	}
+
+	if k8sResume {
+		return resumeKubeadmCreate(ctx, providerOpts, clusterName, debug)
+	}
+
 	// Generate the plan
 	k8sPlan := plan.GenerateKubeadmCreatePlan(plan.KubeadmCreateOptions{
 		ClusterName:       clusterName,
 		Region:            awsRegion,
 		Profile:           awsProfile,
+		ControlPlaneCount: k8sControlPlanes,
 		WorkerCount:       k8sWorkers,
 		NodeType:          k8sNodeType,
 		ControlPlaneType:  k8sNodeType,
 		KubernetesVersion: k8sK8sVersion,
 		KeyPairName:       keyPairName,
 		SSHKeyPath:        sshKeyPath,
+		SSHUser:           providerOpts.SSHUser,
+		JumpHost:          providerOpts.JumpHost,
 		CNI:               "calico",
 	})
 
@@ -1117,7 +1343,7 @@ func runCreateKubeadm(cmd *cobra.Command, args []string) error {
 		fmt.Println("Plan JSON:")
 		planJSON, _ := json.MarshalIndent(k8sPlan, "", "  ")
 		fmt.Println(string(planJSON))
-		return nil
+		return runPlanGeneratedHooks("k8s", planJSON)
 	}
 
 	// Confirm unless --apply
@@ -1134,30 +1360,161 @@ func runCreateKubeadm(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	// Execute using existing kubeadm provider (which has streaming output)
-	agent, _, _ := getK8sAgent()
-	agent.RegisterKubeadmProvider(k8s.KubeadmProviderOptions{
-		AWSProfile:  awsProfile,
-		Region:      awsRegion,
-		KeyPairName: keyPairName,
-		SSHKeyPath:  sshKeyPath,
-	})
-
-	provider, ok := agent.GetClusterProvider(k8s.ClusterTypeKubeadm)
-	if !ok {
-		return fmt.Errorf("kubeadm provider not available")
-	}
-
-	opts := cluster.CreateOptions{
+	return executeKubeadmCreate(ctx, providerOpts, cluster.CreateOptions{
 		Name:              clusterName,
 		Region:            awsRegion,
+		ControlPlaneCount: k8sControlPlanes,
 		WorkerCount:       k8sWorkers,
 		WorkerType:        k8sNodeType,
 		ControlPlaneType:  k8sNodeType,
 		KubernetesVersion: k8sK8sVersion,
+	}, debug)
+}
+
+// runCreateKubeadmHetzner creates a kubeadm cluster on Hetzner Cloud. The
+// plan generator is EC2-specific, so this shows a summary instead.
+func runCreateKubeadmHetzner(cmd *cobra.Command, clusterName string) error {
+	ctx := context.Background()
+	debug := viper.GetBool("debug")
+
+	if k8sKeyPair == "" {
+		return fmt.Errorf("--key-pair is required with --provider hetzner (name of an SSH key in the Hetzner project)")
+	}
+	if k8sSSHKeyPath == "" {
+		return fmt.Errorf("--ssh-key is required with --provider hetzner (private key matching --key-pair)")
+	}
+
+	providerOpts, err := kubeadmProviderOptions("", "", k8sKeyPair, k8sSSHKeyPath)
+	if err != nil {
+		return err
+	}
+	backend := providerOpts.Backend
+
+	if k8sResume {
+		return resumeKubeadmCreate(ctx, providerOpts, clusterName, debug)
+	}
+
+	// --node-type defaults to an EC2 type; let the backend pick unless set
+	nodeType := ""
+	if cmd.Flags().Changed("node-type") {
+		nodeType = k8sNodeType
+	}
+	shownType := nodeType
+	if shownType == "" {
+		shownType = backend.DefaultInstanceType()
+	}
+
+	fmt.Printf("Cluster:     %s (kubeadm on Hetzner Cloud)\n", clusterName)
+	fmt.Printf("Location:    %s\n", backend.Region())
+	servers := k8sControlPlanes + k8sWorkers
+	if k8sControlPlanes > 1 {
+		servers++ // HAProxy load balancer
+	}
+	fmt.Printf("Nodes:       %d control plane + %d worker(s), %s\n", k8sControlPlanes, k8sWorkers, shownType)
+	fmt.Printf("Kubernetes:  %s\n", k8sK8sVersion)
+	fmt.Printf("SSH key:     %s (%s)\n", k8sKeyPair, k8sSSHKeyPath)
+	fmt.Printf("Creates:     network and firewall %s-k8s, %d server(s)\n", clusterName, servers)
+
+	if k8sPlanOnly {
+		return nil
+	}
+
+	// Confirm unless --apply
+	if !k8sApply {
+		fmt.Print("Do you want to create this cluster? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	fmt.Println()
+
+	return executeKubeadmCreate(ctx, providerOpts, cluster.CreateOptions{
+		Name:              clusterName,
+		Region:            backend.Region(),
+		ControlPlaneCount: k8sControlPlanes,
+		WorkerCount:       k8sWorkers,
+		WorkerType:        nodeType,
+		ControlPlaneType:  nodeType,
+		KubernetesVersion: k8sK8sVersion,
+	}, debug)
+}
+
+// resumeKubeadmCreate shows where an interrupted creation stopped and,
+// once confirmed, continues it
+func resumeKubeadmCreate(ctx context.Context, providerOpts k8s.KubeadmProviderOptions, clusterName string, debug bool) error {
+	state, err := cluster.LoadKubeadmCreateState(clusterName)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no interrupted creation found for cluster %s", clusterName)
+	}
+
+	joined := 0
+	for _, w := range state.Workers {
+		if w.Joined {
+			joined++
+		}
+	}
+	fmt.Printf("Cluster:     %s (kubeadm on %s, %s)\n", state.ClusterName, state.Backend, state.Region)
+	fmt.Printf("Completed:   %s\n", state.Step)
+	fmt.Printf("Workers:     %d of %d joined\n", joined, state.WorkerCount)
+	if state.LastError != "" {
+		fmt.Printf("Last error:  %s\n", state.LastError)
+	}
+
+	// Confirm unless --apply
+	if !k8sApply {
+		fmt.Print("Do you want to resume creating this cluster? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	fmt.Println()
+
+	return executeKubeadmCreate(ctx, providerOpts, cluster.CreateOptions{Name: clusterName}, debug)
+}
+
+// executeKubeadmCreate runs kubeadm cluster creation (or resumes it with
+// --resume) and prints the result
+func executeKubeadmCreate(ctx context.Context, providerOpts k8s.KubeadmProviderOptions, opts cluster.CreateOptions, debug bool) error {
+	agent, _, _ := getK8sAgent()
+	agent.RegisterKubeadmProvider(providerOpts)
+
+	provider, ok := agent.GetClusterProvider(k8s.ClusterTypeKubeadm)
+	if !ok {
+		return fmt.Errorf("kubeadm provider not available")
 	}
 
-	info, err := provider.Create(ctx, opts)
+	clusterName := opts.Name
+	var info *cluster.ClusterInfo
+	var err error
+	if k8sResume {
+		kubeadm, ok := provider.(*cluster.KubeadmProvider)
+		if !ok {
+			return fmt.Errorf("kubeadm provider does not support resume")
+		}
+		info, err = kubeadm.Resume(ctx, clusterName)
+	} else {
+		info, err = provider.Create(ctx, opts)
+	}
 	if err != nil {
+		if state, _ := cluster.LoadKubeadmCreateState(clusterName); state != nil {
+			providerFlag := ""
+			if isHetznerKubeadm() {
+				providerFlag = " --provider hetzner"
+			}
+			fmt.Fprintf(os.Stderr, "[k8s] the cluster's instances were kept. Continue with 'clanker k8s create kubeadm %s --resume%s' or remove them with 'clanker k8s delete kubeadm %s%s'\n",
+				clusterName, providerFlag, clusterName, providerFlag)
+		}
 		return fmt.Errorf("failed to create kubeadm cluster: %w", err)
 	}
 
@@ -1205,6 +1562,7 @@ func runCreateKubeadm(cmd *cobra.Command, args []string) error {
 	}
 
 	plan.DisplayConnection(os.Stdout, result.Connection)
+	notifyClusterCreated("kubeadm", info, result.Connection.Kubeconfig)
 
 	return nil
 }
@@ -1219,8 +1577,37 @@ func runCreateGKE(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// The plan and the create call share the same options
+	opts := cluster.CreateOptions{
+		Name:              clusterName,
+		Region:            gcpRegion,
+		WorkerCount:       k8sNodes,
+		WorkerType:        k8sNodeType,
+		KubernetesVersion: k8sK8sVersion,
+		GCPProject:        gcpProject,
+		Preemptible:       k8sGKEPreemptible,
+		GKEAutopilot:      k8sGKEAutopilot,
+	}
+	if k8sGKEChannel != "" {
+		channel, err := cluster.NormalizeGKEReleaseChannel(k8sGKEChannel)
+		if err != nil {
+			return err
+		}
+		opts.GKEReleaseChannel = channel
+	}
+	if k8sGKEAutoProv {
+		if k8sGKEMaxCPU <= 0 || k8sGKEMaxMemoryGB <= 0 {
+			return fmt.Errorf("--enable-autoprovisioning needs --max-cpu and --max-memory")
+		}
+		opts.GKEAutoProvisioning = &cluster.GKEAutoProvisioning{MaxCPU: k8sGKEMaxCPU, MaxMemoryGB: k8sGKEMaxMemoryGB}
+	}
+
+	if err := cluster.ValidateGKECreateOptions(opts); err != nil {
+		return err
+	}
+
 	// Generate the plan
-	gkePlan := plan.GenerateGKECreatePlan(plan.GKECreateOptions{
+	planOpts := plan.GKECreateOptions{
 		ClusterName:       clusterName,
 		Project:           gcpProject,
 		Region:            gcpRegion,
@@ -1228,7 +1615,14 @@ func runCreateGKE(cmd *cobra.Command, args []string) error {
 		NodeType:          k8sNodeType,
 		KubernetesVersion: k8sK8sVersion,
 		Preemptible:       k8sGKEPreemptible,
-	})
+		Autopilot:         opts.GKEAutopilot,
+		ReleaseChannel:    opts.GKEReleaseChannel,
+	}
+	if opts.GKEAutoProvisioning != nil {
+		planOpts.AutoProvisioningMaxCPU = opts.GKEAutoProvisioning.MaxCPU
+		planOpts.AutoProvisioningMaxMemoryGB = opts.GKEAutoProvisioning.MaxMemoryGB
+	}
+	gkePlan := plan.GenerateGKECreatePlan(planOpts)
 
 	// Display the plan
 	plan.DisplayPlan(os.Stdout, gkePlan, plan.PlanDisplayOptions{
@@ -1242,7 +1636,7 @@ func runCreateGKE(cmd *cobra.Command, args []string) error {
 		fmt.Println("Plan JSON:")
 		planJSON, _ := json.MarshalIndent(gkePlan, "", "  ")
 		fmt.Println(string(planJSON))
-		return nil
+		return runPlanGeneratedHooks("k8s", planJSON)
 	}
 
 	// Confirm unless --apply
@@ -1258,17 +1652,6 @@ func runCreateGKE(cmd *cobra.Command, args []string) error {
 
 	fmt.Println()
 
-	// Execute using agent
-	opts := cluster.CreateOptions{
-		Name:              clusterName,
-		Region:            gcpRegion,
-		WorkerCount:       k8sNodes,
-		WorkerType:        k8sNodeType,
-		KubernetesVersion: k8sK8sVersion,
-		GCPProject:        gcpProject,
-		Preemptible:       k8sGKEPreemptible,
-	}
-
 	info, err := agent.CreateGKECluster(ctx, opts)
 	if err != nil {
 		return fmt.Errorf("failed to create GKE cluster: %w", err)
@@ -1299,6 +1682,7 @@ func runCreateGKE(cmd *cobra.Command, args []string) error {
 
 	// Display result
 	plan.DisplayResult(os.Stdout, gkePlan, result)
+	notifyClusterCreated("GKE", info, result.Connection.Kubeconfig)
 
 	return nil
 }
@@ -1356,7 +1740,7 @@ func runCreateAKS(cmd *cobra.Command, args []string) error {
 		fmt.Println("Plan JSON:")
 		planJSON, _ := json.MarshalIndent(opts, "", "  ")
 		fmt.Println(string(planJSON))
-		return nil
+		return runPlanGeneratedHooks("k8s", planJSON)
 	}
 
 	if !k8sApply {
@@ -1401,6 +1785,7 @@ func runCreateAKS(cmd *cobra.Command, args []string) error {
 	fmt.Println("Next steps:")
 	fmt.Println("  kubectl get nodes")
 	fmt.Println("  kubectl get pods -A")
+	notifyClusterCreated("AKS", info, kubeconfigPath)
 
 	return nil
 }
@@ -1410,6 +1795,10 @@ func runDeleteCluster(cmd *cobra.Command, args []string) error {
 	clusterName := args[1]
 	ctx := context.Background()
 
+	if k8sDeleteDryRun {
+		return previewClusterDelete(ctx, clusterType, clusterName)
+	}
+
 	// Handle GKE separately
 	if clusterType == "gke" {
 		agent, gcpProject, gcpRegion, err := getK8sAgentWithGKE()
@@ -1491,6 +1880,43 @@ func runDeleteCluster(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
+	// Handle kubeadm on Hetzner Cloud separately — the delete plan below
+	// lists EC2 commands
+	if clusterType == "kubeadm" && isHetznerKubeadm() {
+		providerOpts, err := kubeadmProviderOptions("", "", "", "")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("kubeadm cluster delete plan (Hetzner Cloud):")
+		fmt.Printf("  Name:     %s\n", clusterName)
+		fmt.Printf("  Deletes:  servers labelled clanker.io/cluster=%s, network and firewall %s-k8s\n", clusterName, clusterName)
+
+		fmt.Print("\nAre you sure you want to delete this cluster? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+
+		fmt.Println()
+		fmt.Printf("[k8s] deleting kubeadm cluster '%s' on Hetzner Cloud...\n", clusterName)
+
+		agent, _, _ := getK8sAgent()
+		agent.RegisterKubeadmProvider(providerOpts)
+		provider, ok := agent.GetClusterProvider(k8s.ClusterTypeKubeadm)
+		if !ok {
+			return fmt.Errorf("kubeadm provider not available")
+		}
+		if err := provider.Delete(ctx, clusterName); err != nil {
+			return fmt.Errorf("failed to delete cluster: %w", err)
+		}
+
+		fmt.Printf("[k8s] cluster '%s' deleted successfully.\n", clusterName)
+		return nil
+	}
+
 	// Handle EKS and kubeadm
 	agent, awsProfile, awsRegion := getK8sAgent()
 
@@ -1543,6 +1969,223 @@ func runDeleteCluster(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runUpgradeCluster(cmd *cobra.Command, args []string) error {
+	clusterType := strings.ToLower(args[0])
+	clusterName := args[1]
+	ctx := context.Background()
+	debug := viper.GetBool("debug")
+
+	target, err := cluster.KubernetesMinor(k8sUpgradeVersion)
+	if err != nil {
+		return err
+	}
+
+	agent, profile, region, err := clusterLifecycleAgent(clusterType)
+	if err != nil {
+		return err
+	}
+
+	provider, ok := agent.GetClusterProvider(k8s.ClusterType(clusterType))
+	if !ok {
+		return fmt.Errorf("%s provider not available", clusterType)
+	}
+
+	// Managed providers report their version up front, so an impossible
+	// upgrade fails before the confirmation prompt
+	current := ""
+	if info, infoErr := provider.GetCluster(ctx, clusterName); infoErr == nil && info.KubernetesVersion != "" {
+		current = info.KubernetesVersion
+		if err := cluster.CheckUpgradePath(current, target); err != nil {
+			return err
+		}
+	} else if infoErr != nil && debug {
+		fmt.Printf("[k8s] could not read current version: %v\n", infoErr)
+	}
+
+	var backups *backup.SubAgent
+	var backupOpts *backup.BackupOptions
+	var upgradeBackup *plan.UpgradeBackup
+	if !k8sSkipBackup {
+		backups, backupOpts = preUpgradeBackup(ctx, clusterName, debug)
+	}
+	if backupOpts != nil {
+		upgradeBackup = &plan.UpgradeBackup{
+			Name:      backupOpts.Name,
+			Namespace: backup.VeleroNamespace,
+			Manifest:  backup.BackupManifest(*backupOpts),
+		}
+	}
+
+	upgradePlan := plan.GenerateUpgradePlan(plan.UpgradeOptions{
+		ClusterType:    clusterType,
+		ClusterName:    clusterName,
+		Region:         region,
+		Profile:        profile,
+		CurrentVersion: current,
+		TargetVersion:  target,
+		Backup:         upgradeBackup,
+	})
+
+	plan.DisplayPlan(os.Stdout, upgradePlan, plan.PlanDisplayOptions{
+		ShowCommands: debug,
+		Verbose:      debug,
+	})
+
+	if k8sPlanOnly {
+		planJSON, err := json.MarshalIndent(upgradePlan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format plan: %w", err)
+		}
+		fmt.Println(string(planJSON))
+		return nil
+	}
+
+	if !k8sApply {
+		fmt.Print("Do you want to upgrade this cluster? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("[k8s] upgrading %s cluster '%s' to %s (this can take an hour on large clusters)...\n", clusterType, clusterName, target)
+
+	start := time.Now()
+	if backupOpts != nil {
+		fmt.Printf("[k8s] backing up every namespace with Velero as %s...\n", backupOpts.Name)
+		if err := backups.CreateBackup(ctx, *backupOpts); err != nil {
+			return err
+		}
+		if err := backups.WaitForBackup(ctx, backupOpts.Name, backup.DefaultBackupTimeout); err != nil {
+			return fmt.Errorf("pre-upgrade backup failed (use --skip-backup to upgrade without one): %w", err)
+		}
+		fmt.Printf("[k8s] backup %s completed in %s.\n", backupOpts.Name, time.Since(start).Round(time.Second))
+	}
+
+	if err := agent.UpgradeCluster(ctx, k8s.ClusterType(clusterType), clusterName, target); err != nil {
+		return fmt.Errorf("failed to upgrade cluster: %w", err)
+	}
+
+	fmt.Printf("[k8s] cluster '%s' upgraded to %s in %s.\n", clusterName, target, time.Since(start).Round(time.Second))
+	return nil
+}
+
+func runPatchNodes(cmd *cobra.Command, args []string) error {
+	clusterType := strings.ToLower(args[0])
+	clusterName := args[1]
+	ctx := context.Background()
+	debug := viper.GetBool("debug")
+
+	agent, profile, region, err := clusterLifecycleAgent(clusterType)
+	if err != nil {
+		return err
+	}
+
+	patchPlan := plan.GeneratePatchPlan(plan.PatchOptions{
+		ClusterType: clusterType,
+		ClusterName: clusterName,
+		Region:      region,
+		Profile:     profile,
+	})
+
+	plan.DisplayPlan(os.Stdout, patchPlan, plan.PlanDisplayOptions{
+		ShowCommands: debug,
+		Verbose:      debug,
+	})
+
+	if k8sPlanOnly {
+		planJSON, err := json.MarshalIndent(patchPlan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format plan: %w", err)
+		}
+		fmt.Println(string(planJSON))
+		return nil
+	}
+
+	if !k8sApply {
+		fmt.Print("Do you want to patch and reboot every node of this cluster? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("[k8s] patching nodes of %s cluster '%s' one at a time (this can take an hour on large clusters)...\n", clusterType, clusterName)
+
+	start := time.Now()
+	if err := agent.PatchClusterNodes(ctx, k8s.ClusterType(clusterType), clusterName, cluster.PatchOptions{Progress: os.Stdout}); err != nil {
+		return fmt.Errorf("failed to patch nodes: %w", err)
+	}
+
+	fmt.Printf("[k8s] all nodes of cluster '%s' patched in %s.\n", clusterName, time.Since(start).Round(time.Second))
+	return nil
+}
+
+// preUpgradeBackup returns the Velero backup taken before an upgrade. It
+// goes through the current kubectl context, so it is only taken when that
+// context names the cluster being upgraded and Velero is installed there.
+func preUpgradeBackup(ctx context.Context, clusterName string, debug bool) (*backup.SubAgent, *backup.BackupOptions) {
+	client := k8s.NewClient("", "", debug)
+	current, err := client.GetCurrentContext(ctx)
+	if err != nil || !strings.Contains(current, clusterName) {
+		fmt.Printf("[k8s] kubectl context is not cluster '%s'; no Velero backup is taken before the upgrade\n", clusterName)
+		return nil, nil
+	}
+
+	backups := backup.NewSubAgent(k8s.NewBackupAdapter(client), debug)
+	if !backups.Installed(ctx) {
+		fmt.Printf("[k8s] Velero is not installed in %s; no backup is taken before the upgrade\n", current)
+		return nil, nil
+	}
+	opts := backup.PreUpgradeBackup(clusterName)
+	return backups, &opts
+}
+
+// clusterLifecycleAgent returns an agent with the provider of clusterType
+// registered for upgrades and node patching, along with the profile (AWS
+// profile, GCP project or Azure resource group) and region plans show
+func clusterLifecycleAgent(clusterType string) (agent *k8s.Agent, profile, region string, err error) {
+	switch clusterType {
+	case "eks":
+		agent, profile, region = getK8sAgent()
+	case "gke":
+		agent, profile, region, err = getK8sAgentWithGKE()
+		if err != nil {
+			return nil, "", "", err
+		}
+	case "aks":
+		subscription, resourceGroup, azureRegion := getAKSConfig()
+		if resourceGroup == "" {
+			return nil, "", "", fmt.Errorf("Azure resource group is required (use --azure-resource-group or set infra.azure.resource_group)")
+		}
+		agent = k8s.NewAgentWithOptions(k8s.AgentOptions{Debug: viper.GetBool("debug")})
+		agent.RegisterAKSProvider(subscription, resourceGroup, azureRegion)
+		profile, region = resourceGroup, azureRegion
+	case "kubeadm":
+		var awsProfile, awsRegion string
+		agent, awsProfile, awsRegion = getK8sAgent()
+		providerOpts, optsErr := kubeadmProviderOptions(awsProfile, awsRegion, "", k8sSSHKeyPath)
+		if optsErr != nil {
+			return nil, "", "", optsErr
+		}
+		agent.RegisterKubeadmProvider(providerOpts)
+		profile, region = awsProfile, awsRegion
+		if providerOpts.Backend != nil {
+			profile, region = "", providerOpts.Backend.Region()
+		}
+	default:
+		return nil, "", "", fmt.Errorf("unsupported cluster type: %s (use 'eks', 'gke', 'aks', or 'kubeadm')", clusterType)
+	}
+
+	return agent, profile, region, nil
+}
+
 func runListClusters(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
@@ -1604,6 +2247,13 @@ func runListClusters(cmd *cobra.Command, args []string) error {
 		}
 		clusters, err = providerCtx.agent.ListAKSClusters(ctx)
 	case "kubeadm":
+		if isHetznerKubeadm() {
+			providerOpts, optsErr := kubeadmProviderOptions("", "", "", "")
+			if optsErr != nil {
+				return optsErr
+			}
+			providerCtx.agent.RegisterKubeadmProvider(providerOpts)
+		}
 		provider, ok := providerCtx.agent.GetClusterProvider(k8s.ClusterTypeKubeadm)
 		if !ok {
 			return fmt.Errorf("kubeadm provider not available. Configure AWS CLI or use kubeconfig contexts instead")
@@ -1662,7 +2312,7 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 		fmt.Println("Plan JSON:")
 		planJSON, _ := json.MarshalIndent(deployPlan, "", "  ")
 		fmt.Println(string(planJSON))
-		return nil
+		return runPlanGeneratedHooks("k8s", planJSON)
 	}
 
 	// Confirm
@@ -1726,6 +2376,7 @@ spec:
 	fmt.Println(output)
 	fmt.Println()
 	fmt.Println("=== Deployment Successful ===")
+	recordAppliedPlan("deploy", deployPlan.Summary, getCurrentContext(ctx), k8sNamespace, manifest)
 
 	plan.DisplayConnection(os.Stdout, deployPlan.Connection)
 
@@ -1766,10 +2417,11 @@ func runGetKubeconfig(cmd *cobra.Command, args []string) error {
 		kubeconfigPath, err = providerCtx.agent.GetAKSKubeconfig(ctx, clusterName)
 	case "kubeadm":
 		agent, awsProfile, awsRegion := getK8sAgent()
-		agent.RegisterKubeadmProvider(k8s.KubeadmProviderOptions{
-			AWSProfile: awsProfile,
-			Region:     awsRegion,
-		})
+		providerOpts, optsErr := kubeadmProviderOptions(awsProfile, awsRegion, "", k8sSSHKeyPath)
+		if optsErr != nil {
+			return optsErr
+		}
+		agent.RegisterKubeadmProvider(providerOpts)
 		provider, ok := agent.GetClusterProvider(k8s.ClusterTypeKubeadm)
 		if !ok {
 			return fmt.Errorf("kubeadm provider not available")
@@ -1794,25 +2446,28 @@ func runGetKubeconfig(cmd *cobra.Command, args []string) error {
 }
 
 func runGetResources(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
-	debug := viper.GetBool("debug")
+	resources, err := loadClusterResources(context.Background(), viper.GetBool("debug"))
+	if err != nil {
+		return err
+	}
+	if err := writeK8sOutput(resources); err != nil {
+		return fmt.Errorf("failed to marshal resources: %w", err)
+	}
+	return nil
+}
 
+// loadClusterResources gets the resources of --cluster, or of the current
+// kubectl context when it is not set
+func loadClusterResources(ctx context.Context, debug bool) (*k8s.ClusterResources, error) {
 	providerCtx := getK8sAgentWithAvailableProviders()
 
 	if k8sClusterName != "" {
-		resources, err := getNamedClusterResources(ctx, providerCtx, k8sClusterName, debug)
-		if err != nil {
-			return err
-		}
-		if err := writeK8sOutput(resources); err != nil {
-			return fmt.Errorf("failed to marshal resources: %w", err)
-		}
-		return nil
+		return getNamedClusterResources(ctx, providerCtx, k8sClusterName, debug)
 	}
 
 	originalContext := getCurrentContext(ctx)
 	if originalContext == "" {
-		return fmt.Errorf("no cluster specified and no current kubectl context is set")
+		return nil, fmt.Errorf("no cluster specified and no current kubectl context is set")
 	}
 
 	if debug {
@@ -1821,16 +2476,12 @@ func runGetResources(cmd *cobra.Command, args []string) error {
 
 	resources, err := getResourcesFromContext(ctx, originalContext, providerCtx.kubeconfigPath, originalContext, debug)
 	if err != nil {
-		return fmt.Errorf("failed to get cluster resources from current context %q: %w", originalContext, err)
+		return nil, fmt.Errorf("failed to get cluster resources from current context %q: %w", originalContext, err)
 	}
 	if !hasClusterResourceData(resources) {
-		return fmt.Errorf("current context %q did not return any cluster resources", originalContext)
+		return nil, fmt.Errorf("current context %q did not return any cluster resources", originalContext)
 	}
-
-	if err := writeK8sOutput(resources); err != nil {
-		return fmt.Errorf("failed to marshal resources: %w", err)
-	}
-	return nil
+	return resources, nil
 }
 
 // verifyEKSClusterExists checks if an EKS cluster exists in the specified region
@@ -2501,12 +3152,38 @@ func runK8sAsk(cmd *cobra.Command, args []string) error {
 	if k8sNamespace != "" {
 		k8sClient.SetNamespace(k8sNamespace)
 	}
+	k8sClient.SetListOptions(k8sListOptions())
 
 	// Verify cluster connection
 	if err := k8sClient.CheckConnection(ctx); err != nil {
 		return fmt.Errorf("cannot connect to Kubernetes cluster: %w\nTry running: aws eks update-kubeconfig --name <cluster-name> --profile %s", err, awsProfile)
 	}
 
+	// Watch mode bypasses the LLM and polls the listing directly
+	if k8sAskWatch {
+		watchOpts, ok := k8s.ParseWatchQuery(question)
+		if !ok {
+			return fmt.Errorf("--watch needs a read-only listing such as \"watch pods in namespace foo\"")
+		}
+		if k8sNamespace != "" {
+			watchOpts.Namespace = k8sNamespace
+		}
+		return runK8sWatch(cmd.Context(), k8sClient, watchOpts, true)
+	}
+
+	// Context switches and cross-context comparisons bypass the LLM
+	if intent, ok := k8s.ParseContextQuery(question); ok {
+		if intent.Action == k8s.ContextActionCompare && intent.Namespace == "" {
+			intent.Namespace = k8sNamespace
+		}
+		result, err := k8s.ExecuteContextIntent(ctx, k8sClient, intent)
+		if err != nil {
+			return err
+		}
+		fmt.Print(result)
+		return nil
+	}
+
 	// Determine cluster name for conversation history
 	clusterName := k8sAskCluster
 	if clusterName == "" {
@@ -2719,7 +3396,7 @@ func createAIClient(debug bool) (*ai.Client, error) {
 
 	if model := strings.TrimSpace(k8sAskModel); model != "" {
 		configKey := fmt.Sprintf("ai.providers.%s.model", provider)
-		viper.Set(configKey, model)
+		setFlagOverride(configKey, model)
 	}
 
 	return ai.NewClient(provider, apiKey, debug, provider), nil
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	k8sTokenProvider     string
+	k8sTokenSubscription string
+)
+
+// k8sTokenCmd is the exec credential plugin behind token-exchange access
+// for GKE and AKS. kubectl and client-go run it; it is not meant for users.
+var k8sTokenCmd = &cobra.Command{
+	Use:    "token",
+	Short:  "Print a cluster bearer token as an ExecCredential",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cred, err := k8s.ProviderToken(context.Background(), k8sTokenProvider, k8sTokenSubscription)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(os.Stdout).Encode(cred)
+	},
+}
+
+func init() {
+	k8sCmd.AddCommand(k8sTokenCmd)
+	k8sTokenCmd.Flags().StringVar(&k8sTokenProvider, "provider", "", "Token provider (gke, aks)")
+	k8sTokenCmd.Flags().StringVar(&k8sTokenSubscription, "subscription", "", "Azure subscription for the az token")
+
+	k8sCmd.PersistentFlags().String("access", "", "Reach the cluster with provider tokens instead of kubeconfig (eks/<cluster>, gke/<cluster>, aks/<cluster>)")
+	_ = viper.BindPFlag("kubernetes.access.target", k8sCmd.PersistentFlags().Lookup("access"))
+}
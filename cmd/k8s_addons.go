@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/k8s/cluster"
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	k8sAddonVersion string
+	k8sAddonRoleARN string
+)
+
+var k8sAddonCmd = &cobra.Command{
+	Use:   "addon",
+	Short: "Manage EKS add-ons (vpc-cni, coredns, kube-proxy, ebs-csi, metrics-server)",
+	Long: `List, install and upgrade EKS managed add-ons. Add-ons whose service
+account needs AWS permissions (vpc-cni, aws-ebs-csi-driver) take an IRSA
+role with --service-account-role-arn; the plan shows how to create one.`,
+}
+
+var k8sAddonListCmd = &cobra.Command{
+	Use:   "list [cluster-name]",
+	Short: "List add-ons installed on an EKS cluster",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runK8sAddonList,
+}
+
+var k8sAddonVersionsCmd = &cobra.Command{
+	Use:   "versions [cluster-name] [addon]",
+	Short: "List add-on versions compatible with a cluster",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runK8sAddonVersions,
+}
+
+var k8sAddonInstallCmd = &cobra.Command{
+	Use:   "install [cluster-name] [addon]",
+	Short: "Install an EKS add-on",
+	Long: `Install an EKS add-on at the default version for the cluster, or at
+--version.
+
+Example:
+  clanker k8s addon install my-cluster metrics-server
+  clanker k8s addon install my-cluster ebs-csi --service-account-role-arn arn:aws:iam::123456789012:role/my-cluster-aws-ebs-csi-driver-irsa
+  clanker k8s addon install my-cluster ebs-csi --plan  # Show plan, including IRSA role steps`,
+	Args: cobra.ExactArgs(2),
+	RunE: runK8sAddonInstall,
+}
+
+var k8sAddonUpgradeCmd = &cobra.Command{
+	Use:   "upgrade [cluster-name] [addon]",
+	Short: "Upgrade an EKS add-on",
+	Long: `Upgrade an installed EKS add-on to --version, or to the newest version
+for the cluster's Kubernetes version. Configuration changed on the cluster
+is preserved.
+
+Example:
+  clanker k8s addon upgrade my-cluster coredns
+  clanker k8s addon upgrade my-cluster vpc-cni --version v1.18.3-eksbuild.1`,
+	Args: cobra.ExactArgs(2),
+	RunE: runK8sAddonUpgrade,
+}
+
+func init() {
+	k8sCmd.AddCommand(k8sAddonCmd)
+	k8sAddonCmd.AddCommand(k8sAddonListCmd)
+	k8sAddonCmd.AddCommand(k8sAddonVersionsCmd)
+	k8sAddonCmd.AddCommand(k8sAddonInstallCmd)
+	k8sAddonCmd.AddCommand(k8sAddonUpgradeCmd)
+
+	for _, cmd := range []*cobra.Command{k8sAddonInstallCmd, k8sAddonUpgradeCmd} {
+		cmd.Flags().StringVar(&k8sAddonVersion, "version", "", "Add-on version (default: the cluster's default for install, newest for upgrade)")
+		cmd.Flags().StringVar(&k8sAddonRoleARN, "service-account-role-arn", "", "IAM role the add-on's service account assumes (IRSA)")
+		cmd.Flags().BoolVar(&k8sPlanOnly, "plan", false, "Show plan without applying")
+		cmd.Flags().BoolVar(&k8sApply, "apply", false, "Apply the plan (default prompts for confirmation)")
+	}
+}
+
+func newEKSProvider() (*cluster.EKSProvider, string, string) {
+	profile, region := resolveAWSK8sConfig()
+	provider := cluster.NewEKSProvider(cluster.EKSProviderOptions{
+		AWSProfile: profile,
+		Region:     region,
+		Debug:      viper.GetBool("debug"),
+	})
+	return provider, profile, region
+}
+
+// resolveAddonName maps aliases such as "ebs-csi" to the EKS add-on name;
+// unknown names are passed through so any EKS add-on can be managed
+func resolveAddonName(name string) (string, cluster.EKSAddonSpec) {
+	if spec, ok := cluster.LookupEKSAddon(name); ok {
+		return spec.Name, spec
+	}
+	return name, cluster.EKSAddonSpec{Name: name}
+}
+
+func runK8sAddonList(cmd *cobra.Command, args []string) error {
+	provider, _, _ := newEKSProvider()
+
+	addons, err := provider.ListAddons(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+	if len(addons) == 0 {
+		fmt.Printf("No add-ons installed on %s\n", args[0])
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tSTATUS\tIRSA ROLE")
+	for _, addon := range addons {
+		role := addon.ServiceAccountRoleARN
+		if role == "" {
+			role = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", addon.Name, addon.Version, addon.Status, role)
+	}
+	w.Flush()
+
+	for _, addon := range addons {
+		for _, issue := range addon.Issues {
+			fmt.Printf("  %s: %s\n", addon.Name, issue)
+		}
+	}
+	return nil
+}
+
+func runK8sAddonVersions(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	provider, _, _ := newEKSProvider()
+	addonName, _ := resolveAddonName(args[1])
+
+	info, err := provider.GetCluster(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	versions, err := provider.AddonVersions(ctx, addonName, info.KubernetesVersion)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		fmt.Printf("No %s versions for Kubernetes %s\n", addonName, info.KubernetesVersion)
+		return nil
+	}
+
+	fmt.Printf("%s versions for Kubernetes %s:\n", addonName, info.KubernetesVersion)
+	for _, v := range versions {
+		if v.Default {
+			fmt.Printf("  %s (default)\n", v.Version)
+		} else {
+			fmt.Printf("  %s\n", v.Version)
+		}
+	}
+	return nil
+}
+
+func runK8sAddonInstall(cmd *cobra.Command, args []string) error {
+	return runK8sAddonChange(args[0], args[1], false)
+}
+
+func runK8sAddonUpgrade(cmd *cobra.Command, args []string) error {
+	return runK8sAddonChange(args[0], args[1], true)
+}
+
+func runK8sAddonChange(clusterName, addon string, upgrade bool) error {
+	ctx := context.Background()
+	debug := viper.GetBool("debug")
+	provider, profile, region := newEKSProvider()
+	addonName, spec := resolveAddonName(addon)
+
+	k8sVersion := ""
+	if info, err := provider.GetCluster(ctx, clusterName); err == nil {
+		k8sVersion = info.KubernetesVersion
+	} else if debug {
+		fmt.Printf("[k8s] could not read cluster version: %v\n", err)
+	}
+
+	planOpts := plan.AddonOptions{
+		ClusterName:       clusterName,
+		Region:            region,
+		Profile:           profile,
+		Addon:             addonName,
+		Version:           k8sAddonVersion,
+		KubernetesVersion: k8sVersion,
+		Upgrade:           upgrade,
+	}
+	// Only plan a new IRSA role when none was given
+	if spec.NeedsIRSA() && k8sAddonRoleARN == "" {
+		planOpts.Namespace = spec.Namespace
+		planOpts.ServiceAccount = spec.ServiceAccount
+		planOpts.PolicyARN = spec.PolicyARN
+	}
+	addonPlan := plan.GenerateAddonPlan(planOpts)
+
+	plan.DisplayPlan(os.Stdout, addonPlan, plan.PlanDisplayOptions{
+		ShowCommands: debug,
+		Verbose:      debug,
+	})
+
+	if k8sPlanOnly {
+		planJSON, err := json.MarshalIndent(addonPlan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format plan: %w", err)
+		}
+		fmt.Println(string(planJSON))
+		return nil
+	}
+
+	if planOpts.PolicyARN != "" {
+		// The provider does not create IAM roles; without one the add-on
+		// falls back to the node role, which usually lacks the permissions
+		fmt.Printf("Warning: %s needs an IAM role for %s/%s. Create it with the plan's IRSA steps and pass --service-account-role-arn.\n",
+			addonName, spec.Namespace, spec.ServiceAccount)
+	}
+
+	if !k8sApply {
+		verb := "install"
+		if upgrade {
+			verb = "upgrade"
+		}
+		fmt.Printf("Do you want to %s %s? [y/N]: ", verb, addonName)
+		var response string
+		fmt.Scanln(&response)
+		if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	opts := cluster.AddonOptions{
+		Name:                  addonName,
+		Version:               k8sAddonVersion,
+		ServiceAccountRoleARN: k8sAddonRoleARN,
+	}
+
+	start := time.Now()
+	if upgrade {
+		fmt.Printf("[k8s] upgrading add-on %s on %s...\n", addonName, clusterName)
+		if err := provider.UpgradeAddon(ctx, clusterName, opts); err != nil {
+			return fmt.Errorf("failed to upgrade add-on: %w", err)
+		}
+	} else {
+		fmt.Printf("[k8s] installing add-on %s on %s...\n", addonName, clusterName)
+		if err := provider.InstallAddon(ctx, clusterName, opts); err != nil {
+			return fmt.Errorf("failed to install add-on: %w", err)
+		}
+	}
+
+	fmt.Printf("[k8s] add-on %s is active on %s (%s).\n", addonName, clusterName, time.Since(start).Round(time.Second))
+	return nil
+}
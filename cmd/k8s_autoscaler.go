@@ -77,6 +77,8 @@ func runAutoscalerAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("autoscaler analysis failed: %w", err)
 	}
 
+	runReportHooks("k8s autoscaler analyze", report)
+
 	switch strings.ToLower(autoscalerOutput) {
 	case "json":
 		enc := json.NewEncoder(os.Stdout)
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/telemetry"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	k8sCapacityOutput     string
+	k8sCapacityKubeconfig string
+	k8sCapacityContext    string
+)
+
+var k8sCapacityCmd = &cobra.Command{
+	Use:   "capacity",
+	Short: "Report requests vs limits vs usage per node pool, node, and namespace",
+	Long: `Compare what the cluster can hold with what workloads request, are
+allowed to use (limits), and actually use, per node pool, node, and
+namespace.
+
+The report flags:
+  - node pools above 85% CPU or memory requested
+  - nodes whose limits overcommit allocatable by 1.5x or more
+  - lightly requested nodes whose pods could be packed elsewhere
+  - namespaces using under 25% of what they request
+  - pending pods the scheduler cannot place, including pods too large for
+    any node
+
+Usage columns need metrics-server; without it the report still covers
+requests, limits, and unschedulable pods. Read-only: only kubectl get and
+kubectl top are invoked.
+
+Examples:
+  clanker k8s capacity
+  clanker k8s capacity -o json
+  clanker k8s capacity --context prod`,
+	RunE: runK8sCapacity,
+}
+
+func init() {
+	k8sCmd.AddCommand(k8sCapacityCmd)
+	k8sCapacityCmd.Flags().StringVarP(&k8sCapacityOutput, "output", "o", "table", "Output format (table, json)")
+	k8sCapacityCmd.Flags().StringVar(&k8sCapacityKubeconfig, "kubeconfig", "", "Path to kubeconfig (default: ~/.kube/config)")
+	k8sCapacityCmd.Flags().StringVar(&k8sCapacityContext, "context", "", "kubectl context to use")
+}
+
+func runK8sCapacity(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	debug := viper.GetBool("debug")
+
+	client := k8s.NewClient(k8sCapacityKubeconfig, k8sCapacityContext, debug)
+	metrics := telemetry.NewMetricsManager(k8s.NewTelemetryAdapter(client), debug)
+
+	report, err := metrics.GetCapacityReport(ctx)
+	if err != nil {
+		return fmt.Errorf("capacity analysis failed: %w", err)
+	}
+
+	runReportHooks("k8s capacity", report)
+
+	switch strings.ToLower(k8sCapacityOutput) {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	default:
+		telemetry.WriteCapacityTable(os.Stdout, report)
+		return nil
+	}
+}
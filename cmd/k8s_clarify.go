@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/manifestgen"
+)
+
+// maxClarifications bounds the questions asked about one query
+const maxClarifications = 3
+
+// Clarification fields handleK8sDeployment asks about, alongside the
+// agent's k8s.ClarifyContext and k8s.ClarifyResource
+const (
+	clarifyImage = "image"
+	clarifyPort  = "port"
+)
+
+// askClarification asks c on the terminal and returns the answer: an
+// option's number or name, or free text. Without a terminal it prints c as
+// a needs_clarification JSON response for the caller to answer by asking
+// again, more specifically, and returns "".
+func askClarification(c *k8s.Clarification) (string, error) {
+	if !isStdinTerminal() {
+		out, err := json.MarshalIndent(struct {
+			Type k8s.ResponseType `json:"type"`
+			*k8s.Clarification
+		}{k8s.ResponseTypeNeedsClarification, c}, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to format clarification: %w", err)
+		}
+		fmt.Println(string(out))
+		return "", nil
+	}
+
+	fmt.Fprintln(os.Stderr, c.Question)
+	for i, option := range c.Options {
+		fmt.Fprintf(os.Stderr, "  %d) %s\n", i+1, option)
+	}
+	fmt.Fprint(os.Stderr, "> ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	answer := clarificationAnswer(c, line)
+	if answer == "" {
+		return "", fmt.Errorf("no answer given; ask again naming the %s", strings.ReplaceAll(c.Field, "_", " "))
+	}
+	return answer, nil
+}
+
+// clarificationAnswer reads a typed answer, where a number picks an option
+func clarificationAnswer(c *k8s.Clarification, line string) string {
+	answer := strings.TrimSpace(line)
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(c.Options) {
+		return c.Options[n-1]
+	}
+	return answer
+}
+
+// completeDeploySpec asks for the image when the deploy request named none
+// and, for a serving workload, for the port when the image is not a well
+// known one. It reports false when a question went unanswered, with the
+// error if there was one.
+func completeDeploySpec(spec *manifestgen.AppSpec, hasImage bool) (bool, error) {
+	if !hasImage {
+		image, err := askClarification(&k8s.Clarification{
+			Question: "Which container image should I deploy? For example nginx:1.27 or myorg/api:1.2",
+			Field:    clarifyImage,
+		})
+		if err != nil || image == "" {
+			return false, err
+		}
+		spec.SetImage(image)
+	}
+	if spec.Port == 0 && !spec.IsBatch() {
+		answer, err := askClarification(&k8s.Clarification{
+			Question: fmt.Sprintf("Which port does %s listen on?", spec.Image),
+			Field:    clarifyPort,
+		})
+		if err != nil || answer == "" {
+			return false, err
+		}
+		port, err := strconv.Atoi(answer)
+		if err != nil || port < 1 || port > 65535 {
+			return false, fmt.Errorf("invalid port %q", answer)
+		}
+		spec.Port = int32(port)
+	}
+	return true, nil
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+)
+
+func TestClarificationAnswer(t *testing.T) {
+	c := &k8s.Clarification{Question: "Which deployment?", Field: k8s.ClarifyResource, Options: []string{"web-api", "web-worker"}}
+	for line, want := range map[string]string{
+		"2\n":         "web-worker",
+		" web-api \n": "web-api",
+		"3\n":         "3",
+		"billing\r\n": "billing",
+		"\n":          "",
+	} {
+		if got := clarificationAnswer(c, line); got != want {
+			t.Errorf("clarificationAnswer(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
@@ -75,6 +75,8 @@ func runK8sCost(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("workload cost attribution failed: %w", err)
 	}
 
+	runReportHooks("k8s cost", report)
+
 	switch strings.ToLower(k8sCostOutput) {
 	case "json":
 		enc := json.NewEncoder(os.Stdout)
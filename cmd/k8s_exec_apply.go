@@ -187,6 +187,13 @@ func runK8sApply(cmd *cobra.Command, args []string) error {
 	if !strings.HasSuffix(output, "\n") {
 		fmt.Println()
 	}
+	if !k8sApplyServerDry {
+		kubeContext := k8sCOpsContext
+		if kubeContext == "" {
+			kubeContext, _ = client.GetCurrentContext(ctx)
+		}
+		recordAppliedPlan("apply", "", kubeContext, k8sCOpsNamespace, manifest)
+	}
 	return nil
 }
 
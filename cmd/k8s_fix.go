@@ -11,6 +11,7 @@ import (
 	"strings"
 	"text/tabwriter"
 
+	"github.com/bgdnvk/clanker/internal/dryrun"
 	"github.com/bgdnvk/clanker/internal/k8s"
 	"github.com/bgdnvk/clanker/internal/k8s/sre"
 	"github.com/spf13/cobra"
@@ -112,7 +113,7 @@ func runK8sFix(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("marshal playbook plan: %w", err)
 		}
 		fmt.Println(string(out))
-		return nil
+		return runPlanGeneratedHooks("k8s fix", out)
 	}
 
 	printPlaybookPlan(os.Stdout, plan)
@@ -233,6 +234,9 @@ func executePlaybookPlan(ctx context.Context, plan *sre.PlaybookPlan, approve st
 // the --namespace / --context flags — so we don't need to re-thread
 // kubeconfig.
 func runPlaybookStep(ctx context.Context, step sre.PlaybookStep) (string, error) {
+	if dryrun.Skip(os.Stdout, step.Command, step.Args) {
+		return "", nil
+	}
 	cmd := exec.CommandContext(ctx, step.Command, step.Args...)
 	cmd.Env = os.Environ()
 	out, err := cmd.CombinedOutput()
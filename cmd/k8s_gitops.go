@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	ghclient "github.com/bgdnvk/clanker/internal/github"
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/gitops"
+	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/spf13/viper"
+)
+
+// exportK8sPlanGitOps writes an approved K8s plan into the configured GitOps
+// repository as a pull request instead of applying it. repoOverride
+// (owner/name) wins over gitops.repo.
+func exportK8sPlanGitOps(ctx context.Context, rawPlan, repoOverride string, debug bool) error {
+	var plan k8s.K8sPlan
+	if err := json.Unmarshal([]byte(rawPlan), &plan); err != nil {
+		return fmt.Errorf("failed to parse K8s plan: %w", err)
+	}
+	if len(plan.HelmCmds) == 0 && len(plan.KubectlCmds) == 0 && len(plan.Manifests) == 0 {
+		return fmt.Errorf("--gitops needs a K8s plan with manifests, helm or kubectl steps; cluster provisioning plans must be applied directly")
+	}
+
+	repo := strings.TrimSpace(repoOverride)
+	if repo == "" {
+		repo = strings.TrimSpace(viper.GetString("gitops.repo"))
+	}
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" {
+		return fmt.Errorf("gitops repository must be owner/name (set gitops.repo or --gitops-repo), got %q", repo)
+	}
+
+	export, err := gitops.ExportPlan(&plan, gitops.Options{
+		Dir:           strings.Trim(viper.GetString("gitops.path"), "/"),
+		Format:        viper.GetString("gitops.format"),
+		ArgoNamespace: viper.GetString("gitops.argocd_namespace"),
+	})
+	if err != nil {
+		return err
+	}
+
+	summary := strings.TrimSpace(plan.Summary)
+	if summary == "" {
+		summary = "Apply K8s plan"
+	}
+	branch := fmt.Sprintf("clanker/%s-%s", secfile.SafeSlug(strings.Join(strings.Fields(strings.ToLower(summary)), "-")), time.Now().UTC().Format("20060102-150405"))
+	if debug {
+		fmt.Printf("[gitops] %d file(s) for %s on branch %s\n", len(export.Files), repo, branch)
+	}
+
+	client := ghclient.NewClient(viper.GetString("github.token"), owner, name)
+	url, err := client.CreateFilesPullRequest(ctx, ghclient.FilesPullRequest{
+		Base:   viper.GetString("gitops.base"),
+		Branch: branch,
+		Title:  "clanker: " + summary,
+		Body:   gitopsPullRequestBody(&plan, export),
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("[gitops] opened %s\n", url)
+	for _, step := range export.Skipped {
+		fmt.Printf("[gitops] not exported: %s\n", step)
+	}
+	return nil
+}
+
+// gitopsPullRequestBody lists what the PR changes and what it leaves out
+func gitopsPullRequestBody(plan *k8s.K8sPlan, export *gitops.Export) string {
+	var sb strings.Builder
+	sb.WriteString(plan.Summary)
+	sb.WriteString("\n\nGenerated by `clanker ask --apply --gitops`. Merge to let Argo CD or Flux sync the change.\n")
+
+	paths := make([]string, 0, len(export.Files))
+	for path := range export.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	sb.WriteString("\n### Files\n")
+	for _, path := range paths {
+		fmt.Fprintf(&sb, "- `%s`\n", path)
+	}
+
+	if len(export.Skipped) > 0 {
+		sb.WriteString("\n### Not exported\nThese steps have no declarative form; run them after the sync if still needed:\n")
+		for _, step := range export.Skipped {
+			fmt.Fprintf(&sb, "- `%s`\n", step)
+		}
+	}
+	if len(plan.Warnings) > 0 {
+		sb.WriteString("\n### Warnings\n")
+		for _, w := range plan.Warnings {
+			fmt.Fprintf(&sb, "- %s\n", w)
+		}
+	}
+	if len(plan.Notes) > 0 {
+		sb.WriteString("\n### Notes\n")
+		for _, n := range plan.Notes {
+			fmt.Fprintf(&sb, "- %s\n", n)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bgdnvk/clanker/internal/k8s/cluster"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	k8sGKEEnable  bool
+	k8sGKEDisable bool
+)
+
+var k8sGKECmd = &cobra.Command{
+	Use:   "gke",
+	Short: "Manage GKE release channels, node auto-provisioning and maintenance",
+	Long: `Change how GKE upgrades and sizes an existing cluster, and see when it
+will next do so. Autopilot clusters are created with
+'clanker k8s create gke --autopilot'.`,
+}
+
+var k8sGKEHealthCmd = &cobra.Command{
+	Use:   "health [cluster-name]",
+	Short: "Show GKE cluster health, release channel and upcoming auto-upgrades",
+	Long: `Show the health of a GKE cluster with its node pools, release channel,
+node auto-provisioning, next maintenance window, and any control plane
+auto-upgrade the release channel has queued.
+
+Example:
+  clanker k8s gke health my-cluster --gcp-project my-project`,
+	Args: cobra.ExactArgs(1),
+	RunE: runK8sGKEHealth,
+}
+
+var k8sGKEChannelCmd = &cobra.Command{
+	Use:   "release-channel [cluster-name] [channel]",
+	Short: "Move a GKE cluster to another release channel",
+	Long: `Enroll a GKE cluster in the rapid, regular, stable or extended release
+channel, or take it off release channels with none. GKE upgrades the
+cluster to the channel's versions in its maintenance windows.
+
+Example:
+  clanker k8s gke release-channel my-cluster stable --gcp-project my-project`,
+	Args: cobra.ExactArgs(2),
+	RunE: runK8sGKEChannel,
+}
+
+var k8sGKEAutoProvCmd = &cobra.Command{
+	Use:   "autoprovisioning [cluster-name]",
+	Short: "Turn GKE node auto-provisioning on or off",
+	Long: `Turn node auto-provisioning on, letting GKE create node pools for pods
+that fit no existing pool, within --max-cpu vCPUs and --max-memory GB for
+the whole cluster. Turning it off keeps the node pools it created.
+
+Example:
+  clanker k8s gke autoprovisioning my-cluster --enable --max-cpu 64 --max-memory 256
+  clanker k8s gke autoprovisioning my-cluster --disable`,
+	Args: cobra.ExactArgs(1),
+	RunE: runK8sGKEAutoProv,
+}
+
+func init() {
+	k8sCmd.AddCommand(k8sGKECmd)
+	k8sGKECmd.AddCommand(k8sGKEHealthCmd)
+	k8sGKECmd.AddCommand(k8sGKEChannelCmd)
+	k8sGKECmd.AddCommand(k8sGKEAutoProvCmd)
+
+	for _, cmd := range []*cobra.Command{k8sGKEHealthCmd, k8sGKEChannelCmd, k8sGKEAutoProvCmd} {
+		cmd.Flags().StringVar(&k8sGCPProject, "gcp-project", "", "GCP project ID for GKE clusters")
+		cmd.Flags().StringVar(&k8sGCPRegion, "gcp-region", "", "GCP region for GKE clusters")
+	}
+	for _, cmd := range []*cobra.Command{k8sGKEChannelCmd, k8sGKEAutoProvCmd} {
+		cmd.Flags().BoolVar(&k8sApply, "apply", false, "Apply without prompting for confirmation")
+	}
+	k8sGKEAutoProvCmd.Flags().BoolVar(&k8sGKEEnable, "enable", false, "Turn node auto-provisioning on")
+	k8sGKEAutoProvCmd.Flags().BoolVar(&k8sGKEDisable, "disable", false, "Turn node auto-provisioning off")
+	k8sGKEAutoProvCmd.Flags().IntVar(&k8sGKEMaxCPU, "max-cpu", 0, "Most vCPUs node auto-provisioning may run in the cluster")
+	k8sGKEAutoProvCmd.Flags().IntVar(&k8sGKEMaxMemoryGB, "max-memory", 0, "Most memory in GB node auto-provisioning may run in the cluster")
+	k8sGKEAutoProvCmd.MarkFlagsMutuallyExclusive("enable", "disable")
+	k8sGKEAutoProvCmd.MarkFlagsOneRequired("enable", "disable")
+}
+
+func newGKEProvider() (*cluster.GKEProvider, error) {
+	project, region := getGCPConfig()
+	if project == "" {
+		return nil, fmt.Errorf("GCP project is required. Use --gcp-project flag or set GCP_PROJECT environment variable")
+	}
+	return cluster.NewGKEProvider(cluster.GKEProviderOptions{
+		ProjectID: project,
+		Region:    region,
+		Debug:     viper.GetBool("debug"),
+	}), nil
+}
+
+// confirmK8sChange asks before changing a cluster unless --apply was given
+func confirmK8sChange(question string) bool {
+	if k8sApply {
+		return true
+	}
+	fmt.Printf("%s [y/N]: ", question)
+	var response string
+	fmt.Scanln(&response)
+	return strings.ToLower(response) == "y" || strings.ToLower(response) == "yes"
+}
+
+func runK8sGKEHealth(cmd *cobra.Command, args []string) error {
+	provider, err := newGKEProvider()
+	if err != nil {
+		return err
+	}
+	health, err := provider.Health(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+	printGKEHealth(os.Stdout, args[0], health)
+	return nil
+}
+
+// printGKEHealth prints a cluster's health with its components sorted by
+// name
+func printGKEHealth(out io.Writer, clusterName string, health *cluster.HealthStatus) {
+	state := "healthy"
+	if !health.Healthy {
+		state = "unhealthy"
+	}
+	fmt.Fprintf(out, "GKE cluster %s: %s\n  %s\n\n", clusterName, state, health.Message)
+
+	names := make([]string, 0, len(health.Components))
+	for name := range health.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPONENT\tSTATUS")
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\n", name, health.Components[name])
+	}
+	w.Flush()
+
+	if upgrade, ok := health.Components["auto-upgrade"]; ok {
+		fmt.Fprintf(out, "\nGKE will upgrade the %s. Add a maintenance exclusion to hold it back.\n", upgrade)
+	}
+}
+
+func runK8sGKEChannel(cmd *cobra.Command, args []string) error {
+	channel, err := cluster.NormalizeGKEReleaseChannel(args[1])
+	if err != nil {
+		return err
+	}
+	provider, err := newGKEProvider()
+	if err != nil {
+		return err
+	}
+
+	if channel != "none" {
+		fmt.Printf("GKE will upgrade %s to %s channel versions in its maintenance windows.\n", args[0], channel)
+	} else {
+		fmt.Printf("%s will stay on its current version until it reaches end of support.\n", args[0])
+	}
+	if !confirmK8sChange(fmt.Sprintf("Move %s to the %s release channel?", args[0], channel)) {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	if err := provider.SetReleaseChannel(context.Background(), args[0], channel); err != nil {
+		return err
+	}
+	fmt.Printf("[k8s] %s is on the %s release channel.\n", args[0], channel)
+	return nil
+}
+
+func runK8sGKEAutoProv(cmd *cobra.Command, args []string) error {
+	provider, err := newGKEProvider()
+	if err != nil {
+		return err
+	}
+
+	var limits *cluster.GKEAutoProvisioning
+	question := fmt.Sprintf("Turn off node auto-provisioning on %s?", args[0])
+	if k8sGKEEnable {
+		if k8sGKEMaxCPU <= 0 || k8sGKEMaxMemoryGB <= 0 {
+			return fmt.Errorf("--enable needs --max-cpu and --max-memory")
+		}
+		limits = &cluster.GKEAutoProvisioning{MaxCPU: k8sGKEMaxCPU, MaxMemoryGB: k8sGKEMaxMemoryGB}
+		question = fmt.Sprintf("Turn on node auto-provisioning on %s, up to %d vCPUs and %d GB?", args[0], k8sGKEMaxCPU, k8sGKEMaxMemoryGB)
+	}
+	if !confirmK8sChange(question) {
+		fmt.Println("Cancelled.")
+		return nil
+	}
+
+	if err := provider.SetNodeAutoProvisioning(context.Background(), args[0], limits); err != nil {
+		return err
+	}
+	if limits != nil {
+		fmt.Printf("[k8s] node auto-provisioning is on for %s.\n", args[0])
+	} else {
+		fmt.Printf("[k8s] node auto-provisioning is off for %s.\n", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s/cluster"
+)
+
+func TestK8sGKECmd_Wiring(t *testing.T) {
+	for _, name := range []string{"autopilot", "release-channel", "enable-autoprovisioning", "max-cpu", "max-memory"} {
+		if k8sCreateGKECmd.Flags().Lookup(name) == nil {
+			t.Errorf("k8s create gke is missing --%s flag", name)
+		}
+	}
+
+	subcommands := map[string]bool{}
+	for _, c := range k8sGKECmd.Commands() {
+		subcommands[strings.SplitN(c.Use, " ", 2)[0]] = true
+	}
+	for _, name := range []string{"health", "release-channel", "autoprovisioning"} {
+		if !subcommands[name] {
+			t.Errorf("k8s gke %s not registered", name)
+		}
+	}
+
+	if err := k8sGKEChannelCmd.Args(k8sGKEChannelCmd, []string{"my-cluster"}); err == nil {
+		t.Error("release-channel should require a channel")
+	}
+}
+
+func TestPrintGKEHealth(t *testing.T) {
+	var buf bytes.Buffer
+	printGKEHealth(&buf, "prod", &cluster.HealthStatus{
+		Healthy: true,
+		Message: "cluster RUNNING, 3/3 nodes ready",
+		Components: map[string]string{
+			"cluster":            "RUNNING",
+			"release-channel":    "regular",
+			"maintenance-window": "next 2026-10-16 03:00 UTC for 4h",
+			"auto-upgrade":       "control plane to 1.31.2-gke.300 in the window starting 2026-10-16 03:00 UTC",
+		},
+	})
+	out := buf.String()
+	for _, want := range []string{
+		"GKE cluster prod: healthy",
+		"release-channel     regular",
+		"GKE will upgrade the control plane to 1.31.2-gke.300 in the window starting 2026-10-16 03:00 UTC.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	// Components are listed by name
+	if strings.Index(out, "\nauto-upgrade") > strings.Index(out, "\ncluster ") {
+		t.Errorf("components not sorted:\n%s", out)
+	}
+}
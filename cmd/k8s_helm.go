@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/helm"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -31,6 +35,11 @@ var (
 	k8sHelmKeepHistory     bool
 	k8sHelmRollbackRev     int
 	k8sHelmOutputFormat    string
+	k8sHelmSearchLimit     int
+	k8sHelmSearchVersions  bool
+	k8sHelmSearchInstall   int
+	k8sHelmSearchRelease   string
+	k8sHelmSearchNoHub     bool
 )
 
 var k8sHelmCmd = &cobra.Command{
@@ -61,7 +70,8 @@ var k8sHelmUpgradeCmd = &cobra.Command{
 	Use:   "upgrade [release] [chart]",
 	Short: "Upgrade an existing Helm release",
 	Long: `Upgrade an existing Helm release. With --install, will install the
-release if it does not already exist (helm upgrade --install).
+release if it does not already exist (helm upgrade --install). Waits for
+the release to become ready (5m timeout) unless --wait=false or --dry-run.
 
 Example:
   clanker k8s helm upgrade my-nginx bitnami/nginx --install -n web
@@ -119,6 +129,11 @@ var k8sHelmRollbackCmd = &cobra.Command{
 	Short: "Roll a Helm release back to a previous revision",
 	Long: `Roll a Helm release back to a previous revision (0 = previous).
 
+The release history is checked first: chart or major version changes, CRD
+changes, and failed or missing target revisions are printed as warnings.
+Waits for the release to become ready (5m timeout) unless --wait=false or
+--dry-run.
+
 Example:
   clanker k8s helm rollback my-nginx 3 -n web
   clanker k8s helm rollback my-app 0`,
@@ -133,6 +148,25 @@ var k8sHelmValuesCmd = &cobra.Command{
 	RunE:  runK8sHelmValues,
 }
 
+var k8sHelmSearchCmd = &cobra.Command{
+	Use:   "search [keyword]",
+	Short: "Find charts in configured repositories and ArtifactHub",
+	Long: `Find charts for an application across the configured Helm repositories
+and ArtifactHub, ranked by name match, official/verified publisher, stars,
+and whether the repository is already configured.
+
+--install N prints an install plan for result N (repository add, pinned
+version, sensible default values) that 'clanker ask --apply' can run.
+
+Example:
+  clanker k8s helm search postgres
+  clanker k8s helm search redis --versions
+  clanker k8s helm search postgres --install 1 --release db -n data > plan.json
+  clanker ask --apply --plan-file plan.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runK8sHelmSearch,
+}
+
 func init() {
 	k8sCmd.AddCommand(k8sHelmCmd)
 	k8sHelmCmd.AddCommand(k8sHelmInstallCmd)
@@ -143,11 +177,13 @@ func init() {
 	k8sHelmCmd.AddCommand(k8sHelmHistoryCmd)
 	k8sHelmCmd.AddCommand(k8sHelmRollbackCmd)
 	k8sHelmCmd.AddCommand(k8sHelmValuesCmd)
+	k8sHelmCmd.AddCommand(k8sHelmSearchCmd)
 
 	// Shared connection / namespace flags on every subcommand.
 	for _, cmd := range []*cobra.Command{
 		k8sHelmInstallCmd, k8sHelmUpgradeCmd, k8sHelmListCmd, k8sHelmUninstallCmd,
 		k8sHelmStatusCmd, k8sHelmHistoryCmd, k8sHelmRollbackCmd, k8sHelmValuesCmd,
+		k8sHelmSearchCmd,
 	} {
 		cmd.Flags().StringVarP(&k8sHelmNamespace, "namespace", "n", "default", "Kubernetes namespace")
 		cmd.Flags().StringVar(&k8sHelmContext, "context", "", "kubectl context to use")
@@ -173,6 +209,12 @@ func init() {
 	k8sHelmUpgradeCmd.Flags().BoolVar(&k8sHelmForce, "force", false, "Force resource updates through delete/recreate")
 	k8sHelmUpgradeCmd.Flags().BoolVar(&k8sHelmInstall, "install", false, "Install the release if it does not already exist")
 
+	// rollback flags
+	k8sHelmRollbackCmd.Flags().BoolVar(&k8sHelmWait, "wait", false, "Wait until all resources are in ready state (default on)")
+	k8sHelmRollbackCmd.Flags().StringVar(&k8sHelmTimeout, "timeout", "", "Time to wait for any individual k8s operation (default 5m0s)")
+	k8sHelmRollbackCmd.Flags().BoolVar(&k8sHelmDryRun, "dry-run", false, "Simulate without actually rolling back")
+	k8sHelmRollbackCmd.Flags().BoolVar(&k8sHelmForce, "force", false, "Force resource updates through delete/recreate")
+
 	// list flags
 	k8sHelmListCmd.Flags().BoolVarP(&k8sHelmAllNamespaces, "all-namespaces", "A", false, "List releases across all namespaces")
 	k8sHelmListCmd.Flags().StringVarP(&k8sHelmOutputFormat, "output", "o", "table", "Output format (table, json, yaml)")
@@ -182,6 +224,14 @@ func init() {
 	k8sHelmUninstallCmd.Flags().BoolVar(&k8sHelmDryRun, "dry-run", false, "Simulate without actually uninstalling")
 	k8sHelmUninstallCmd.Flags().StringVar(&k8sHelmTimeout, "timeout", "", "Time to wait for any individual k8s operation")
 
+	// search flags
+	k8sHelmSearchCmd.Flags().IntVar(&k8sHelmSearchLimit, "limit", 10, "Maximum number of charts to show")
+	k8sHelmSearchCmd.Flags().BoolVar(&k8sHelmSearchVersions, "versions", false, "Also list recent versions of the best match")
+	k8sHelmSearchCmd.Flags().IntVar(&k8sHelmSearchInstall, "install", 0, "Print an install plan for result N instead of the result table")
+	k8sHelmSearchCmd.Flags().StringVar(&k8sHelmSearchRelease, "release", "", "Release name for --install (default: chart name)")
+	k8sHelmSearchCmd.Flags().BoolVar(&k8sHelmSearchNoHub, "no-artifacthub", false, "Search only the configured repositories")
+	k8sHelmSearchCmd.Flags().StringVarP(&k8sHelmOutputFormat, "output", "o", "table", "Output format (table, json)")
+
 	// status / values output format
 	for _, cmd := range []*cobra.Command{k8sHelmStatusCmd, k8sHelmValuesCmd} {
 		cmd.Flags().StringVarP(&k8sHelmOutputFormat, "output", "o", "", "Output format (json, yaml, table)")
@@ -272,11 +322,12 @@ func runK8sHelmUpgrade(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	client := buildK8sHelmClient()
 
+	wait, timeout := helmReleaseWaitDefaults(cmd)
 	helmArgs := []string{"upgrade", release, chart}
 	helmArgs = appendBoolIf(helmArgs, "--install", k8sHelmInstall)
 	helmArgs = appendIf(helmArgs, "--version", k8sHelmVersion)
-	helmArgs = appendBoolIf(helmArgs, "--wait", k8sHelmWait)
-	helmArgs = appendIf(helmArgs, "--timeout", k8sHelmTimeout)
+	helmArgs = appendBoolIf(helmArgs, "--wait", wait)
+	helmArgs = appendIf(helmArgs, "--timeout", timeout)
 	helmArgs = appendBoolIf(helmArgs, "--dry-run", k8sHelmDryRun)
 	helmArgs = appendBoolIf(helmArgs, "--reuse-values", k8sHelmReuseValues)
 	helmArgs = appendBoolIf(helmArgs, "--reset-values", k8sHelmResetValues)
@@ -295,6 +346,20 @@ func runK8sHelmUpgrade(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// helmReleaseWaitDefaults makes upgrade and rollback wait for the release,
+// with a 5m timeout, unless --wait/--timeout were given or it is a dry run
+func helmReleaseWaitDefaults(cmd *cobra.Command) (bool, string) {
+	if k8sHelmDryRun {
+		return k8sHelmWait, k8sHelmTimeout
+	}
+	wait := k8sHelmWait || !cmd.Flags().Changed("wait")
+	timeout := k8sHelmTimeout
+	if wait && timeout == "" {
+		timeout = "5m0s"
+	}
+	return wait, timeout
+}
+
 func runK8sHelmList(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	client := buildK8sHelmClient()
@@ -363,9 +428,19 @@ func runK8sHelmRollback(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 	client := buildK8sHelmClient()
 
+	rev, err := strconv.Atoi(revision)
+	if err != nil || rev < 0 {
+		return fmt.Errorf("invalid revision %q", revision)
+	}
+	releases := helm.NewReleaseManager(k8s.NewHelmAdapter(client), k8sHelmDebug)
+	for _, warning := range releases.RollbackWarnings(ctx, helm.RollbackOptions{ReleaseName: release, Revision: rev, Namespace: k8sHelmNamespace}) {
+		fmt.Fprintf(os.Stderr, "[helm] warning: %s\n", warning)
+	}
+
+	wait, timeout := helmReleaseWaitDefaults(cmd)
 	helmArgs := []string{"rollback", release, revision}
-	helmArgs = appendBoolIf(helmArgs, "--wait", k8sHelmWait)
-	helmArgs = appendIf(helmArgs, "--timeout", k8sHelmTimeout)
+	helmArgs = appendBoolIf(helmArgs, "--wait", wait)
+	helmArgs = appendIf(helmArgs, "--timeout", timeout)
 	helmArgs = appendBoolIf(helmArgs, "--dry-run", k8sHelmDryRun)
 	helmArgs = appendBoolIf(helmArgs, "--force", k8sHelmForce)
 
@@ -390,3 +465,66 @@ func runK8sHelmValues(cmd *cobra.Command, args []string) error {
 	}
 	return nil
 }
+
+func runK8sHelmSearch(cmd *cobra.Command, args []string) error {
+	keyword := args[0]
+	ctx := context.Background()
+	debug := k8sHelmDebug || viper.GetBool("debug")
+	charts := helm.NewChartManager(k8s.NewHelmAdapter(buildK8sHelmClient()), debug)
+
+	results, err := charts.DiscoverCharts(ctx, keyword, helm.DiscoverOptions{
+		Limit:           k8sHelmSearchLimit,
+		SkipArtifactHub: k8sHelmSearchNoHub,
+	})
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no charts found for %q", keyword)
+	}
+
+	if k8sHelmSearchInstall != 0 {
+		if k8sHelmSearchInstall < 1 || k8sHelmSearchInstall > len(results) {
+			return fmt.Errorf("--install must be between 1 and %d", len(results))
+		}
+		helmPlan := charts.InstallPlanForChart(results[k8sHelmSearchInstall-1], k8sHelmSearchRelease, k8sHelmNamespace)
+		planJSON, err := json.MarshalIndent(k8s.HelmPlanToK8sPlan(helmPlan), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(planJSON))
+		return runPlanGeneratedHooks("k8s helm search", planJSON)
+	}
+
+	var versions []helm.ChartVersion
+	if k8sHelmSearchVersions {
+		versions, err = charts.ChartVersions(ctx, results[0], 10)
+		if err != nil {
+			return err
+		}
+	}
+
+	if k8sHelmOutputFormat == "json" {
+		out := map[string]any{"charts": results}
+		if versions != nil {
+			out["versions"] = versions
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Print(helm.FormatChartSearchResults(results))
+	if versions != nil {
+		fmt.Printf("\nVersions of %s:\n", results[0].Ref())
+		fmt.Printf("%-15s %s\n", "VERSION", "APP VERSION")
+		for _, v := range versions {
+			fmt.Printf("%-15s %s\n", v.Version, v.AppVersion)
+		}
+	}
+	fmt.Printf("\nSeed an install plan: clanker k8s helm search %s --install <#> [--release name] [-n namespace]\n", keyword)
+	return nil
+}
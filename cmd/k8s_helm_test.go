@@ -6,7 +6,7 @@ import (
 )
 
 func TestK8sHelmCmd_HasAllSubcommands(t *testing.T) {
-	want := []string{"install", "upgrade", "list", "uninstall", "status", "history", "rollback", "values"}
+	want := []string{"install", "upgrade", "list", "uninstall", "status", "history", "rollback", "values", "search"}
 	got := make(map[string]bool)
 	for _, c := range k8sHelmCmd.Commands() {
 		got[strings.SplitN(c.Use, " ", 2)[0]] = true
@@ -60,6 +60,8 @@ func runHPAValidate(cmd *cobra.Command, args []string) error {
 
 	report.Findings = filterHPAFindingsBySeverity(report.Findings, hpaValidateSeverity)
 
+	runReportHooks("k8s autoscaler validate", report)
+
 	switch strings.ToLower(autoscalerOutput) {
 	case "json":
 		enc := json.NewEncoder(os.Stdout)
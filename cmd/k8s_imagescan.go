@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/k8s/imagescan"
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+)
+
+// newPlanImageScanner is replaced in tests
+var newPlanImageScanner = imagescan.NewScanner
+
+// makerPlanManifests returns the manifests a maker-format K8s plan pipes to
+// kubectl
+func makerPlanManifests(commands []plan.MakerCommand) []string {
+	var manifests []string
+	for _, cmd := range commands {
+		if len(cmd.Args) > 0 && cmd.Args[0] == "kubectl" && cmd.Stdin != "" {
+			manifests = append(manifests, cmd.Stdin)
+		}
+	}
+	return manifests
+}
+
+// checkPlanImages scans the images a plan deploys and refuses the
+// apply when one carries more critical CVEs than kubernetes.image_scan.max_critical
+// allows, unless allowVulnerable is set. Images that cannot be scanned, for
+// instance because trivy is not installed, are reported but never block.
+func checkPlanImages(ctx context.Context, images []string, summary string, allowVulnerable, debug bool) error {
+	if !imagescan.Enabled() || len(images) == 0 {
+		return nil
+	}
+
+	fmt.Printf("[k8s] scanning %d images for vulnerabilities...\n", len(images))
+	results := newPlanImageScanner().ScanAll(ctx, images)
+	for _, r := range results {
+		if r.Skipped != "" || debug {
+			fmt.Printf("[k8s] %s\n", r)
+		}
+	}
+
+	maxCritical := imagescan.MaxCritical()
+	warnings := imagescan.Warnings(results, maxCritical)
+	if len(warnings) == 0 {
+		return nil
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "[k8s] vulnerable %s\n", w)
+	}
+	if !allowVulnerable {
+		return fmt.Errorf("%d images exceed the critical vulnerability limit of %d; rebuild them or re-run with --allow-vulnerable:\n  %s",
+			len(warnings), maxCritical, strings.Join(warnings, "\n  "))
+	}
+	if _, err := audit.Append(audit.Record{
+		Event:   audit.EventImageScanOverride,
+		Command: "ask --apply",
+		Summary: summary,
+		Details: warnings,
+	}); err != nil {
+		return fmt.Errorf("refusing to deploy vulnerable images without an audit entry: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "[k8s] --allow-vulnerable given, applying anyway (recorded in the audit log)")
+	return nil
+}
+
+// noteDeployImageScans scans the images a generated deploy plan ships and
+// records the results as plan notes, so a reviewer sees the CVEs before the
+// apply gate does
+func noteDeployImageScans(ctx context.Context, makerPlan *plan.MakerPlan) {
+	if !imagescan.Enabled() {
+		return
+	}
+	images := imagescan.ManifestImages(makerPlanManifests(makerPlan.Commands)...)
+	if len(images) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[k8s] scanning %d images for vulnerabilities...\n", len(images))
+	results := newPlanImageScanner().ScanAll(ctx, images)
+	for _, r := range results {
+		makerPlan.Notes = append(makerPlan.Notes, "Image scan: "+r.String())
+	}
+	if warnings := imagescan.Warnings(results, imagescan.MaxCritical()); len(warnings) > 0 {
+		makerPlan.Notes = append(makerPlan.Notes, warnings...)
+		makerPlan.Notes = append(makerPlan.Notes, "Apply is blocked unless --allow-vulnerable is given")
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/k8s/imagescan"
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+)
+
+func stubImageScanner(t *testing.T, critical int) {
+	t.Helper()
+	orig := newPlanImageScanner
+	t.Cleanup(func() { newPlanImageScanner = orig })
+	out := `{"Results":[{"Vulnerabilities":[`
+	for i := 0; i < critical; i++ {
+		if i > 0 {
+			out += ","
+		}
+		out += `{"VulnerabilityID":"CVE-2024-` + string(rune('1'+i)) + `","PkgName":"openssl","Severity":"CRITICAL"}`
+	}
+	out += `]}]}`
+	newPlanImageScanner = func() *imagescan.Scanner {
+		return imagescan.NewScannerWithRunner(func(ctx context.Context, args ...string) ([]byte, error) {
+			return []byte(out), nil
+		})
+	}
+}
+
+func TestCheckPlanImagesBlocksCriticalCVEs(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	stubImageScanner(t, 1)
+
+	err := checkPlanImages(context.Background(), []string{"web:1"}, "deploy web", false, false)
+	if err == nil || !strings.Contains(err.Error(), "--allow-vulnerable") {
+		t.Fatalf("err = %v, want a block mentioning --allow-vulnerable", err)
+	}
+
+	if err := checkPlanImages(context.Background(), []string{"web:1"}, "deploy web", true, false); err != nil {
+		t.Fatalf("allowVulnerable should apply: %v", err)
+	}
+	records, err := audit.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Event != audit.EventImageScanOverride || records[0].Summary != "deploy web" {
+		t.Fatalf("audit records = %+v", records)
+	}
+}
+
+func TestCheckPlanImagesAllowsCleanImages(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	stubImageScanner(t, 0)
+	if err := checkPlanImages(context.Background(), []string{"web:1"}, "deploy web", false, false); err != nil {
+		t.Fatalf("clean image blocked: %v", err)
+	}
+}
+
+func TestNoteDeployImageScans(t *testing.T) {
+	stubImageScanner(t, 2)
+	makerPlan := &plan.MakerPlan{Commands: []plan.MakerCommand{
+		{Args: []string{"kubectl", "apply", "-f", "-"}, Stdin: "kind: Pod\nspec:\n  containers:\n    - name: web\n      image: web:1\n"},
+		{Args: []string{"kubectl", "rollout", "status", "deployment/web"}},
+	}}
+	noteDeployImageScans(context.Background(), makerPlan)
+	notes := strings.Join(makerPlan.Notes, "\n")
+	for _, want := range []string{"Image scan: web:1: 2 critical, 0 high", "CVE-2024-1, CVE-2024-2", "--allow-vulnerable"} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("notes missing %q:\n%s", want, notes)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// impersonatedApply runs the kubectl and helm steps of a K8s plan as a
+// limited identity and keeps a plan run log of who each step ran as. A nil
+// *impersonatedApply is valid and leaves steps untouched.
+type impersonatedApply struct {
+	identity *k8s.Impersonation
+	summary  string
+	debug    bool
+	verified bool
+	runLog   *maker.PlanLogWriter
+}
+
+func newImpersonatedApply(identity *k8s.Impersonation, summary string, debug bool) *impersonatedApply {
+	if identity == nil {
+		return nil
+	}
+	fmt.Printf("[k8s] kubectl and helm steps will run as %s\n", identity)
+	a := &impersonatedApply{identity: identity, summary: summary, debug: debug}
+	runLog, err := maker.NewPlanLogWriter("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[k8s] warning: impersonation run log unavailable: %v\n", err)
+		return a
+	}
+	a.runLog = runLog
+	return a
+}
+
+// stepArgs returns args with the impersonation flags for kubectl and helm.
+// The identity is verified against the API server before the first such
+// step, which lets cluster-creation plans impersonate once the cluster
+// exists.
+func (a *impersonatedApply) stepArgs(ctx context.Context, step int, binary string, args []string) ([]string, error) {
+	if a == nil {
+		return args, nil
+	}
+	if binary == "kubectl" || binary == "helm" {
+		if !a.verified {
+			client := a.client(a.debug)
+			username, err := client.VerifyImpersonation(ctx)
+			if err != nil {
+				a.event("impersonation_denied", err.Error())
+				return nil, err
+			}
+			a.verified = true
+			a.event("impersonation", fmt.Sprintf("plan %q: kubectl/helm steps run as %s; API server identity %s", a.summary, a.identity, username))
+		}
+		args = a.identity.CommandArgs(binary, args)
+	}
+	if a.runLog != nil {
+		a.runLog.RecordCommandStart(step, binary, stepOperation(args))
+	}
+	return args, nil
+}
+
+// client returns a client for the context the steps run against, acting
+// as the impersonated identity when there is one
+func (a *impersonatedApply) client(debug bool) *k8s.Client {
+	client := k8s.NewClient("", "", debug)
+	if a != nil {
+		client.SetImpersonation(a.identity)
+	}
+	return client
+}
+
+// stepDone records a step's outcome in the run log
+func (a *impersonatedApply) stepDone(step int, binary string, args []string, err error) {
+	if a == nil || a.runLog == nil {
+		return
+	}
+	if err != nil {
+		a.runLog.RecordCommandFailure(step, binary, stepOperation(args), err.Error())
+		return
+	}
+	a.runLog.RecordCommandSuccess(step, binary, stepOperation(args), "")
+}
+
+// finish writes the run summary and tells the operator where it is
+func (a *impersonatedApply) finish(runErr error) {
+	if a == nil || a.runLog == nil {
+		return
+	}
+	status := "success"
+	if runErr != nil {
+		status = "failed"
+	}
+	_ = a.runLog.WriteSummary(status, nil)
+	_ = a.runLog.Close()
+	fmt.Printf("[k8s] impersonated run log: %s\n", a.runLog.GetLogDir())
+}
+
+func (a *impersonatedApply) event(eventType, message string) {
+	if a.runLog != nil {
+		a.runLog.WriteEvent(eventType, message)
+	}
+}
+
+// stepOperation names a step by its first non-flag argument, skipping the
+// impersonation flags placed in front of it
+func stepOperation(args []string) string {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
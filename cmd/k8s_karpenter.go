@@ -90,6 +90,8 @@ func runKarpenterList(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	runReportHooks("k8s karpenter list", report)
+
 	switch strings.ToLower(karpenterOutput) {
 	case "json":
 		enc := json.NewEncoder(os.Stdout)
@@ -52,6 +52,8 @@ func runKarpenterRecs(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("karpenter advisor failed: %w", err)
 	}
 
+	runReportHooks("k8s autoscaler recommendations", report)
+
 	switch strings.ToLower(autoscalerOutput) {
 	case "json":
 		enc := json.NewEncoder(os.Stdout)
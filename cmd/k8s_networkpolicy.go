@@ -76,6 +76,8 @@ func runNetworkPolicyAudit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("audit failed: %w", err)
 	}
 
+	runReportHooks("k8s networkpolicy audit", report)
+
 	switch strings.ToLower(npAuditOutput) {
 	case "json":
 		enc := json.NewEncoder(os.Stdout)
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+	"github.com/bgdnvk/clanker/internal/k8s/policy"
+)
+
+// makerPlanOperations lists the kubectl and helm changes of a maker-format
+// K8s plan, including manifests piped to kubectl apply
+func makerPlanOperations(commands []plan.MakerCommand) []policy.Operation {
+	var ops []policy.Operation
+	for _, cmd := range commands {
+		if len(cmd.Args) == 0 {
+			continue
+		}
+		switch cmd.Args[0] {
+		case "kubectl":
+			op, ok := policy.FromKubectl(cmd.Args[1:], "")
+			if !ok {
+				continue
+			}
+			if cmd.Stdin != "" {
+				ops = append(ops, policy.FromManifest(op.Verb, cmd.Stdin, op.Namespace)...)
+				continue
+			}
+			ops = append(ops, op)
+		case "helm":
+			if op, ok := policy.FromHelm(cmd.Args[1:], ""); ok {
+				ops = append(ops, op)
+			}
+		}
+	}
+	return ops
+}
+
+// checkK8sPlanPolicy refuses a plan the operator policy denies unless
+// override is set, and asks before operations the policy holds for approval
+func checkK8sPlanPolicy(ops []policy.Operation, summary string, override bool) error {
+	p, err := policy.Load()
+	if err != nil {
+		return err
+	}
+	violations := p.Check(ops)
+	if len(violations) == 0 {
+		return nil
+	}
+	for _, v := range violations {
+		fmt.Fprintf(os.Stderr, "[k8s] policy %s: %s\n", v.Action, v)
+	}
+	if err := policy.Enforce(violations, policy.EnforceOptions{
+		Override: override,
+		Approve:  confirmPolicyApproval,
+		Source:   "ask --apply",
+		Summary:  summary,
+	}); err != nil {
+		return err
+	}
+	if override {
+		fmt.Fprintln(os.Stderr, "[k8s] --override-policy given, applying anyway (recorded in the audit log)")
+	}
+	return nil
+}
+
+// confirmPolicyApproval asks the operator to approve held operations
+func confirmPolicyApproval(violations []policy.Violation) bool {
+	fmt.Printf("\nThe policy requires approval for %d operations. Approve? [y/N]: ", len(violations))
+	var response string
+	fmt.Scanln(&response)
+	response = strings.ToLower(strings.TrimSpace(response))
+	return response == "y" || response == "yes"
+}
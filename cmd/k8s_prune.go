@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/dryrun"
+	"github.com/bgdnvk/clanker/internal/k8s/prune"
+	"github.com/bgdnvk/clanker/internal/planrun"
+	"github.com/bgdnvk/clanker/internal/planstore"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newPruneClient lists plan objects with the context and identity the
+// deletes run as, so pruning never sees objects it could not delete. It is
+// replaced in tests.
+var newPruneClient = func(impersonated *impersonatedApply, debug bool) prune.K8sClient {
+	return impersonated.client(debug)
+}
+
+// planOwnership labels the objects a stored plan applies with its ID and,
+// once the plan has run, deletes the labelled objects it no longer applies
+type planOwnership struct {
+	PlanID   string // stored plan being applied; empty labels nothing
+	Prune    bool   // delete objects an earlier apply of this plan left behind
+	Replaces string // delete objects the replaced plan applied that this one does not
+
+	applied []prune.Object
+	// untracked is set when a step applied objects the plan cannot name,
+	// such as kubectl apply -f <file>, so pruning would be guesswork
+	untracked bool
+}
+
+func (o *planOwnership) pruning() bool {
+	return o != nil && (o.Prune || o.Replaces != "")
+}
+
+// check refuses pruning for a plan that is not in the store, since nothing
+// it applied would carry a plan ID
+func (o *planOwnership) check() error {
+	if o.pruning() && o.PlanID == "" {
+		return fmt.Errorf("--prune and --replaces need a stored plan; apply it with clanker plan apply <id>")
+	}
+	return nil
+}
+
+// kubectlStdin returns the stdin a kubectl step should run with: its
+// manifest labelled with the plan ID when the step applies one
+func (o *planOwnership) kubectlStdin(args []string, stdin string) string {
+	if o == nil || o.PlanID == "" {
+		return stdin
+	}
+	switch stepOperation(args) {
+	case "apply", "create", "replace":
+	default:
+		return stdin
+	}
+	if stdin == "" {
+		// Imperative creates such as kubectl create secret carry no label
+		// and are never pruned; manifests read from files are unknown
+		if kubectlReadsFiles(args) {
+			o.untracked = true
+		}
+		return stdin
+	}
+	labelled, objects, err := prune.Label(stdin, o.PlanID, kubectlNamespace(args))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[k8s] warning: could not label manifest with plan %s: %v\n", o.PlanID, err)
+		o.untracked = true
+		return stdin
+	}
+	o.applied = append(o.applied, objects...)
+	return labelled
+}
+
+// kubectlReadsFiles reports whether a kubectl command reads manifests from
+// files or a kustomization rather than stdin
+func kubectlReadsFiles(args []string) bool {
+	for i, arg := range args {
+		switch {
+		case (arg == "-f" || arg == "--filename") && i+1 < len(args) && args[i+1] != "-":
+			return true
+		case arg == "-k" || arg == "--kustomize" || strings.HasPrefix(arg, "--kustomize="):
+			return true
+		case strings.HasPrefix(arg, "--filename=") && arg != "--filename=-":
+			return true
+		}
+	}
+	return false
+}
+
+// kubectlNamespace returns the namespace a kubectl command names with -n
+func kubectlNamespace(args []string) string {
+	for i, arg := range args {
+		switch {
+		case (arg == "-n" || arg == "--namespace") && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(arg, "--namespace="):
+			return strings.TrimPrefix(arg, "--namespace=")
+		}
+	}
+	return ""
+}
+
+// pruneOrphans deletes the objects labelled with this plan, or the plan it
+// replaces, that the apply that just finished did not apply
+func (o *planOwnership) pruneOrphans(ctx context.Context, impersonated *impersonatedApply, firstStep int, debug bool) error {
+	if !o.pruning() {
+		return nil
+	}
+	if o.untracked {
+		fmt.Fprintln(os.Stderr, "[k8s] warning: not pruning: the plan applied manifests from files, so which objects it kept is unknown")
+		return nil
+	}
+
+	var sources []string
+	if o.Prune {
+		sources = append(sources, o.PlanID)
+	}
+	if o.Replaces != "" && o.Replaces != o.PlanID {
+		sources = append(sources, o.Replaces)
+	}
+	client := newPruneClient(impersonated, debug)
+	var orphans []prune.Object
+	for _, id := range sources {
+		live, warnings, err := prune.Find(ctx, client, id)
+		if err != nil {
+			return fmt.Errorf("failed to find objects applied by plan %s: %w", id, err)
+		}
+		if debug {
+			for _, w := range warnings {
+				fmt.Fprintf(os.Stderr, "[k8s] %s\n", w)
+			}
+		}
+		orphans = append(orphans, prune.Orphans(live, o.applied)...)
+	}
+	if len(orphans) == 0 {
+		fmt.Println("[k8s] Nothing to prune.")
+		return nil
+	}
+	fmt.Printf("[k8s] Pruning %d object(s) the plan no longer applies\n", len(orphans))
+	return deletePlanObjects(ctx, prune.DeleteOrder(orphans), impersonated, firstStep)
+}
+
+// deletePlanObjects deletes objects one kubectl call at a time, stopping at
+// the first failure
+func deletePlanObjects(ctx context.Context, objects []prune.Object, impersonated *impersonatedApply, firstStep int) error {
+	for i, obj := range objects {
+		step := firstStep + i
+		fmt.Printf("[k8s] deleting %s\n", obj)
+		args, err := impersonated.stepArgs(ctx, step, "kubectl", obj.DeleteArgs())
+		if err != nil {
+			return err
+		}
+		if dryrun.Skip(os.Stdout, "kubectl", args) {
+			continue
+		}
+		stepCtx, done, err := planrun.Step(ctx, "")
+		if err != nil {
+			return err
+		}
+		cmd := exec.CommandContext(stepCtx, "kubectl", args...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		err = done(cmd.Run())
+		impersonated.stepDone(step, "kubectl", args, err)
+		audit.RecordStep("kubectl", args, err)
+		if err != nil {
+			return fmt.Errorf("failed to delete %s: %w", obj, err)
+		}
+	}
+	return nil
+}
+
+// planOwnershipFromFlags reads --prune and --replaces for the stored plan
+// being applied. A --replaces prefix is resolved against the store; a plan
+// already removed from it is taken as given, since its labels remain.
+func planOwnershipFromFlags(cmd *cobra.Command, planID string) *planOwnership {
+	owner := &planOwnership{PlanID: planID}
+	owner.Prune, _ = cmd.Flags().GetBool("prune")
+	owner.Replaces, _ = cmd.Flags().GetString("replaces")
+	if owner.Replaces != "" {
+		if stored, err := planstore.Load(owner.Replaces); err == nil {
+			owner.Replaces = stored.ID
+		}
+	}
+	return owner
+}
+
+func runPlanCleanup(cmd *cobra.Command, args []string) error {
+	planID := args[0]
+	if stored, err := planstore.Load(planID); err == nil {
+		planID = stored.ID
+	}
+	debug := viper.GetBool("debug")
+	ctx, stop := planrun.WithInterrupt(context.Background())
+	defer stop()
+
+	objects, warnings, err := prune.Find(ctx, newPruneClient(nil, debug), planID)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+	if len(objects) == 0 {
+		fmt.Printf("No objects labelled %s=%s.\n", prune.PlanLabel, planID)
+		return nil
+	}
+
+	objects = prune.DeleteOrder(objects)
+	fmt.Printf("Plan %s applied %d object(s) still in the cluster:\n", planID, len(objects))
+	for _, obj := range objects {
+		fmt.Printf("  %s\n", obj)
+	}
+	if yes, _ := cmd.Flags().GetBool("yes"); !yes && !dryrun.Enabled() {
+		fmt.Print("Delete them? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+	return deletePlanObjects(ctx, objects, nil, 1)
+}
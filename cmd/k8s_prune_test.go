@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/prune"
+	"github.com/spf13/viper"
+)
+
+// labelledCluster answers kubectl get with the objects labelled with each
+// plan ID
+type labelledCluster struct {
+	byPlan map[string]string
+}
+
+func (c *labelledCluster) Run(ctx context.Context, args ...string) (string, error) {
+	joined := strings.Join(args, " ")
+	if joined == "api-resources --verbs=list,delete -o name" {
+		return "deployments.apps\nservices\n", nil
+	}
+	for id, items := range c.byPlan {
+		if joined == "get deployments.apps,services -A -l "+prune.PlanLabel+"="+id+" -o json" {
+			return `{"items": [` + items + `]}`, nil
+		}
+	}
+	return "", errors.New("unexpected kubectl " + joined)
+}
+
+func TestPlanOwnershipLabelsApplies(t *testing.T) {
+	owner := &planOwnership{PlanID: "p2"}
+	manifest := "apiVersion: v1\nkind: Service\nmetadata:\n  name: api\n"
+
+	stdin := owner.kubectlStdin([]string{"apply", "-f", "-", "-n", "shop"}, manifest)
+	if !strings.Contains(stdin, prune.PlanLabel+": p2") {
+		t.Errorf("manifest not labelled:\n%s", stdin)
+	}
+	if len(owner.applied) != 1 || owner.applied[0].String() != "shop/service/api" {
+		t.Errorf("applied = %v", owner.applied)
+	}
+
+	if got := owner.kubectlStdin([]string{"rollout", "status", "deployment/api"}, ""); got != "" || owner.untracked {
+		t.Errorf("read-only step changed ownership: %q untracked=%v", got, owner.untracked)
+	}
+	owner.kubectlStdin([]string{"create", "secret", "generic", "pull", "--from-file", ".dockerconfigjson=/tmp/c"}, "")
+	if owner.untracked {
+		t.Error("an imperative create marked the plan untracked")
+	}
+	owner.kubectlStdin([]string{"apply", "-f", "app.yaml"}, "")
+	if !owner.untracked {
+		t.Error("applying a file should mark the plan untracked")
+	}
+
+	unowned := &planOwnership{}
+	if got := unowned.kubectlStdin([]string{"apply", "-f", "-"}, manifest); got != manifest {
+		t.Errorf("a plan outside the store was labelled:\n%s", got)
+	}
+}
+
+func TestPlanOwnershipNeedsStoredPlan(t *testing.T) {
+	if err := (&planOwnership{Prune: true}).check(); err == nil {
+		t.Error("expected --prune to need a stored plan")
+	}
+	if err := (&planOwnership{PlanID: "p1"}).check(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPruneOrphansDeletesWhatThePlanDropped(t *testing.T) {
+	cluster := &labelledCluster{byPlan: map[string]string{
+		"p1": `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "worker", "namespace": "shop"}},
+		       {"apiVersion": "v1", "kind": "Service", "metadata": {"name": "api", "namespace": "shop"}}`,
+		"p2": `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "api", "namespace": "shop"}}`,
+	}}
+	orig := newPruneClient
+	t.Cleanup(func() { newPruneClient = orig })
+	newPruneClient = func(impersonated *impersonatedApply, debug bool) prune.K8sClient { return cluster }
+	viper.Set("dry_run", true)
+	t.Cleanup(func() { viper.Set("dry_run", false) })
+
+	owner := &planOwnership{PlanID: "p2", Prune: true, Replaces: "p1"}
+	owner.kubectlStdin([]string{"apply", "-f", "-", "-n", "shop"}, "apiVersion: v1\nkind: Service\nmetadata:\n  name: api\n")
+
+	out := captureStdout(t, func() {
+		if err := owner.pruneOrphans(context.Background(), nil, 1, false); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out, "kubectl delete deployment.v1.apps/worker --ignore-not-found --wait=false -n shop") {
+		t.Errorf("worker not pruned:\n%s", out)
+	}
+	if strings.Contains(out, "service") {
+		t.Errorf("the re-applied service was pruned:\n%s", out)
+	}
+}
+
+func TestPruneOrphansListsAsTheImpersonatedIdentity(t *testing.T) {
+	cluster := &labelledCluster{byPlan: map[string]string{
+		"p1": `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "api", "namespace": "shop"}}`,
+	}}
+	var listedAs *impersonatedApply
+	orig := newPruneClient
+	t.Cleanup(func() { newPruneClient = orig })
+	newPruneClient = func(impersonated *impersonatedApply, debug bool) prune.K8sClient {
+		listedAs = impersonated
+		return cluster
+	}
+
+	impersonated := &impersonatedApply{identity: &k8s.Impersonation{User: "deployer"}}
+	owner := &planOwnership{PlanID: "p1", Prune: true}
+	owner.kubectlStdin([]string{"apply", "-f", "-", "-n", "shop"}, "apiVersion: v1\nkind: Service\nmetadata:\n  name: api\n")
+	captureStdout(t, func() {
+		if err := owner.pruneOrphans(context.Background(), impersonated, 1, false); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if listedAs != impersonated {
+		t.Error("plan objects were not listed as the identity the deletes run as")
+	}
+}
+
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = orig }()
+	fn()
+	w.Close()
+	data, _ := io.ReadAll(r)
+	return string(data)
+}
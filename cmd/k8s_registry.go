@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/registry"
+)
+
+// Replaced in tests
+var (
+	newRegistryChecker = registry.NewChecker
+	pullSecretExists   = func(ctx context.Context, namespace, name string, debug bool) bool {
+		_, err := k8s.NewClient("", "", debug).RunWithNamespace(ctx, namespace, "get", "secret", name, "-o", "name")
+		return err == nil
+	}
+)
+
+// checkDeployImage confirms a deploy request's image tag exists before the
+// plan is emitted, and works out how the pods authenticate to a private
+// registry. The notes it returns belong on the plan.
+func checkDeployImage(ctx context.Context, image, namespace string, debug bool) (registry.PullAuth, error) {
+	checker := newRegistryChecker()
+	checker.AWSProfile = resolveAWSProfile("")
+	fmt.Fprintf(os.Stderr, "[k8s] checking %s in its registry...\n", image)
+	result, err := checker.Check(ctx, image)
+	if err != nil {
+		return registry.PullAuth{}, err
+	}
+	if debug {
+		fmt.Fprintf(os.Stderr, "[k8s] registry %s (%s): private=%v status=%s %s\n", result.Ref.Host, result.Ref.Kind, result.Private, result.Status, result.Detail)
+	}
+
+	var auth registry.PullAuth
+	switch result.Status {
+	case registry.StatusNotFound:
+		return auth, fmt.Errorf("image %s was not found in %s (%s); push it or fix the tag before deploying", image, result.Ref.Host, result.Detail)
+	case registry.StatusUnverified:
+		auth.Notes = append(auth.Notes, fmt.Sprintf("Could not verify that %s exists: %s", image, result.Detail))
+	}
+	if !result.Private {
+		return auth, nil
+	}
+
+	pull := registry.PlanPullAuth(result.Ref)
+	if pull.DockerConfig != "" && pullSecretExists(ctx, namespace, pull.SecretName, debug) {
+		pull.DockerConfig = ""
+		pull.Notes = []string{fmt.Sprintf("Pods pull %s with the existing imagePullSecret %s", result.Ref.Host, pull.SecretName)}
+	}
+	pull.Notes = append(auth.Notes, pull.Notes...)
+	return pull, nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s/registry"
+)
+
+// stubRegistry makes every registry refuse anonymous pulls, so each image
+// is private, and looks tags up with run
+func stubRegistry(t *testing.T, run registry.Runner, secretExists bool) {
+	t.Helper()
+	origChecker, origExists := newRegistryChecker, pullSecretExists
+	t.Cleanup(func() { newRegistryChecker, pullSecretExists = origChecker, origExists })
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusUnauthorized, Header: http.Header{}, Body: http.NoBody, Request: r}, nil
+	})}
+	newRegistryChecker = func() *registry.Checker { return registry.NewCheckerWith(client, run) }
+	pullSecretExists = func(ctx context.Context, namespace, name string, debug bool) bool { return secretExists }
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestCheckDeployImageRefusesMissingTag(t *testing.T) {
+	stubRegistry(t, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return []byte("manifest unknown"), errors.New("exit status 1")
+	}, false)
+	_, err := checkDeployImage(context.Background(), "ghcr.io/acme/api:nope", "shop", false)
+	if err == nil || !strings.Contains(err.Error(), "ghcr.io/acme/api:nope was not found") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestCheckDeployImagePlansPullSecret(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"auths":{"ghcr.io":{"auth":"eDp5"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	found := func(ctx context.Context, name string, args ...string) ([]byte, error) { return nil, nil }
+
+	stubRegistry(t, found, false)
+	auth, err := checkDeployImage(context.Background(), "ghcr.io/acme/api:1", "shop", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.SecretName != "ghcr-io-pull" || auth.DockerConfig != filepath.Join(dir, "config.json") {
+		t.Fatalf("auth = %+v", auth)
+	}
+
+	// An existing secret is referenced, not recreated
+	stubRegistry(t, found, true)
+	auth, err = checkDeployImage(context.Background(), "ghcr.io/acme/api:1", "shop", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.SecretName != "ghcr-io-pull" || auth.DockerConfig != "" || !strings.Contains(auth.Notes[0], "existing imagePullSecret") {
+		t.Fatalf("auth = %+v", auth)
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/rollout"
+	"github.com/bgdnvk/clanker/internal/planstore"
+	"github.com/spf13/viper"
+)
+
+// newRolloutClient is replaced in tests
+var newRolloutClient = func(debug bool) rollout.K8sClient {
+	return k8s.NewClient("", "", debug)
+}
+
+// handleK8sRollout plans a canary or blue/green rollout of a new image to a
+// running Deployment, using Argo Rollouts or Flagger when the cluster has
+// them and plain Deployments otherwise
+func handleK8sRollout(ctx context.Context, question string, req rollout.Request, debug bool) error {
+	if req.Name == "" {
+		return fmt.Errorf("which deployment should the %s roll out to? Name it, e.g. \"deploy v2 of service api as a canary at 10%%\"", req.Strategy)
+	}
+	if req.Namespace == "" {
+		req.Namespace = viper.GetString("kubernetes.default_namespace")
+	}
+	if req.Namespace == "" {
+		req.Namespace = "default"
+	}
+
+	client := newRolloutClient(debug)
+	target, err := rollout.Inspect(ctx, client, req.Namespace, req.Name)
+	if err != nil {
+		return err
+	}
+	controller, err := rollout.DetectController(ctx, client, req.Controller)
+	if err != nil {
+		return err
+	}
+	if debug {
+		fmt.Fprintf(os.Stderr, "[k8s] %s rollout of %s/%s with %s (service %q)\n", req.Strategy, req.Namespace, req.Name, controller, target.Service)
+	}
+
+	rolloutPlan, err := rollout.Generate(req, target, controller, rollout.Options{
+		Pause: viper.GetDuration("kubernetes.rollouts.step_pause"),
+	})
+	if err != nil {
+		return err
+	}
+
+	makerPlan := rolloutPlan.ToMakerPlan(question)
+	annotateK8sMakerPlanTools(makerPlan)
+	planJSON, err := json.MarshalIndent(makerPlan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Println(string(planJSON))
+
+	if err := runPlanGeneratedHooks("ask k8s rollout", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindK8s, "kubernetes", "ask k8s rollout", question, makerPlan.Summary, planJSON)
+	return nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s/rollout"
+	"github.com/spf13/viper"
+)
+
+// emptyCluster answers every kubectl call as if nothing exists
+type emptyCluster struct{ namespaces []string }
+
+func (c *emptyCluster) Run(ctx context.Context, args ...string) (string, error) {
+	return "", errors.New("not found")
+}
+
+func (c *emptyCluster) GetJSON(ctx context.Context, resourceType, name, namespace string) ([]byte, error) {
+	c.namespaces = append(c.namespaces, namespace)
+	return nil, errors.New("not found")
+}
+
+func TestHandleK8sRolloutNeedsDeployment(t *testing.T) {
+	req := rollout.Request{Strategy: rollout.StrategyCanary, Version: "v2", Weights: []int{10, 100}}
+	err := handleK8sRollout(context.Background(), "canary v2", req, false)
+	if err == nil || !strings.Contains(err.Error(), "which deployment") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestHandleK8sRolloutDefaultsNamespace(t *testing.T) {
+	cluster := &emptyCluster{}
+	orig := newRolloutClient
+	t.Cleanup(func() { newRolloutClient = orig })
+	newRolloutClient = func(debug bool) rollout.K8sClient { return cluster }
+	viper.Set("kubernetes.default_namespace", "shop")
+	t.Cleanup(func() { viper.Set("kubernetes.default_namespace", nil) })
+
+	req, _ := rollout.ParseRequest("deploy v2 of service api as a canary at 10%")
+	err := handleK8sRollout(context.Background(), "deploy v2 of service api as a canary at 10%", req, false)
+	if err == nil || !strings.Contains(err.Error(), "deployment api not found in namespace shop") {
+		t.Fatalf("err = %v", err)
+	}
+	if len(cluster.namespaces) != 1 || cluster.namespaces[0] != "shop" {
+		t.Errorf("looked in %v", cluster.namespaces)
+	}
+}
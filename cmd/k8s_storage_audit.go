@@ -69,6 +69,8 @@ func runStorageAudit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("storage audit failed: %w", err)
 	}
 
+	runReportHooks("k8s storage audit", report)
+
 	switch strings.ToLower(storageAuditOutput) {
 	case "json":
 		enc := json.NewEncoder(os.Stdout)
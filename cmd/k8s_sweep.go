@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/cluster"
+	"github.com/spf13/cobra"
+)
+
+var k8sSweepCmd = &cobra.Command{
+	Use:   "sweep [eks|kubeadm]",
+	Short: "Find and clean up resources left by deleted clusters and failed creations",
+	Long: `Find orphaned cluster resources and choose which to delete.
+
+eks:     load balancers, target groups, volumes, security groups and detached
+         VPC CNI network interfaces tagged to EKS clusters that no longer exist
+kubeadm: security groups, networks and instances left by failed creations
+         (a network without instances, or instances without a control plane)
+
+Nothing is deleted without confirmation unless --apply is given.
+
+Example:
+  clanker k8s sweep eks
+  clanker k8s sweep kubeadm
+  clanker k8s sweep kubeadm --provider hetzner
+  clanker k8s sweep eks --plan   # Only list orphans`,
+	Args: cobra.ExactArgs(1),
+	RunE: runK8sSweep,
+}
+
+func init() {
+	k8sCmd.AddCommand(k8sSweepCmd)
+
+	k8sSweepCmd.Flags().StringVar(&k8sKubeadmProvider, "provider", "aws", "Where kubeadm cluster nodes run (aws or hetzner)")
+	k8sSweepCmd.Flags().BoolVar(&k8sPlanOnly, "plan", false, "List orphaned resources without deleting")
+	k8sSweepCmd.Flags().BoolVar(&k8sApply, "apply", false, "Delete every orphaned resource without prompting")
+}
+
+// teardownProvider returns the cluster provider used by `k8s delete
+// --dry-run` and `k8s sweep`
+func teardownProvider(clusterType string) (cluster.Provider, error) {
+	switch clusterType {
+	case "eks":
+		provider, _, _ := newEKSProvider()
+		return provider, nil
+	case "kubeadm":
+		agent, awsProfile, awsRegion := getK8sAgent()
+		providerOpts, err := kubeadmProviderOptions(awsProfile, awsRegion, "", "")
+		if err != nil {
+			return nil, err
+		}
+		agent.RegisterKubeadmProvider(providerOpts)
+		provider, ok := agent.GetClusterProvider(k8s.ClusterTypeKubeadm)
+		if !ok {
+			return nil, fmt.Errorf("kubeadm provider not available")
+		}
+		return provider, nil
+	default:
+		return nil, fmt.Errorf("unsupported cluster type: %s (use 'eks' or 'kubeadm')", clusterType)
+	}
+}
+
+// previewClusterDelete prints what `k8s delete` would remove and what it
+// would leave behind, without deleting anything
+func previewClusterDelete(ctx context.Context, clusterType, clusterName string) error {
+	provider, err := teardownProvider(clusterType)
+	if err != nil {
+		return err
+	}
+	previewer, ok := provider.(cluster.TeardownPreviewer)
+	if !ok {
+		return fmt.Errorf("--dry-run is not supported for %s clusters", clusterType)
+	}
+
+	report, err := previewer.PreviewDelete(ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to inspect cluster: %w", err)
+	}
+	printTeardownReport(os.Stdout, report)
+	return nil
+}
+
+func printTeardownReport(w io.Writer, report *cluster.TeardownReport) {
+	printResources := func(resources []cluster.TeardownResource) {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  TYPE\tID\tNAME\tDETAIL")
+		for _, res := range resources {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", res.Type, res.ID, orDash(res.Name), orDash(res.Detail))
+		}
+		tw.Flush()
+	}
+
+	removed := report.Removed()
+	fmt.Fprintf(w, "Deleting %s cluster %s removes %d resources:\n", report.ClusterType, report.ClusterName, len(removed))
+	if len(removed) > 0 {
+		printResources(removed)
+	}
+
+	if retained := report.Retained(); len(retained) > 0 {
+		fmt.Fprintf(w, "\nTagged to the cluster but NOT removed (%d):\n", len(retained))
+		printResources(retained)
+	}
+
+	if len(report.Warnings) > 0 {
+		fmt.Fprintln(w, "\nWarnings:")
+		for _, warning := range report.Warnings {
+			fmt.Fprintf(w, "  - %s\n", warning)
+		}
+	}
+
+	fmt.Fprintln(w, "\nDry run: nothing was deleted.")
+}
+
+func runK8sSweep(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	clusterType := args[0]
+
+	provider, err := teardownProvider(clusterType)
+	if err != nil {
+		return err
+	}
+	sweeper, ok := provider.(cluster.OrphanSweeper)
+	if !ok {
+		return fmt.Errorf("sweep is not supported for %s clusters", clusterType)
+	}
+
+	fmt.Printf("[k8s] looking for orphaned %s resources...\n", clusterType)
+	orphans, err := sweeper.FindOrphans(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to find orphaned resources: %w", err)
+	}
+	if len(orphans) == 0 {
+		fmt.Println("No orphaned resources found.")
+		return nil
+	}
+
+	fmt.Printf("\nCleanup plan (%d orphaned resources):\n", len(orphans))
+	printOrphans(os.Stdout, orphans)
+
+	if k8sPlanOnly {
+		return nil
+	}
+
+	selected := orphans
+	if !k8sApply {
+		fmt.Print("\nDelete which resources? [all, none, or numbers like 1,3-5] (default none): ")
+		var response string
+		fmt.Scanln(&response)
+		indexes, err := parseSweepSelection(response, len(orphans))
+		if err != nil {
+			return err
+		}
+		if len(indexes) == 0 {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+		selected = make([]cluster.OrphanResource, 0, len(indexes))
+		for _, i := range indexes {
+			selected = append(selected, orphans[i])
+		}
+	}
+
+	fmt.Printf("[k8s] deleting %d resources...\n", len(selected))
+	if err := sweeper.DeleteOrphans(ctx, selected); err != nil {
+		return fmt.Errorf("cleanup incomplete: %w", err)
+	}
+	fmt.Printf("[k8s] deleted %d orphaned resources.\n", len(selected))
+	return nil
+}
+
+func printOrphans(w io.Writer, orphans []cluster.OrphanResource) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  #\tCLUSTER\tTYPE\tID\tNAME\tREASON")
+	for i, orphan := range orphans {
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%s\t%s\t%s\n", i+1, orphan.Cluster, orphan.Type, orphan.ID, orDash(orphan.Name), orphan.Reason)
+	}
+	tw.Flush()
+}
+
+// parseSweepSelection turns "all", "none", "" or a list of 1-based numbers
+// and ranges ("1,3-5") into sorted 0-based indexes
+func parseSweepSelection(input string, count int) ([]int, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	switch input {
+	case "", "none", "n", "no":
+		return nil, nil
+	case "all", "a", "y", "yes":
+		indexes := make([]int, count)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+
+	seen := make(map[int]bool)
+	var indexes []int
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi := part, part
+		if before, after, ok := strings.Cut(part, "-"); ok {
+			lo, hi = before, after
+		}
+		start, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		end, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || start < 1 || end > count || start > end {
+			return nil, fmt.Errorf("invalid selection %q: use numbers between 1 and %d", part, count)
+		}
+		for n := start; n <= end; n++ {
+			if !seen[n-1] {
+				seen[n-1] = true
+				indexes = append(indexes, n-1)
+			}
+		}
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s/cluster"
+)
+
+func TestParseSweepSelection(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "[]"},
+		{"none", "[]"},
+		{"all", "[0 1 2 3 4]"},
+		{"3", "[2]"},
+		{"1,3-4", "[0 2 3]"},
+		{"4,1,4", "[0 3]"},
+	}
+	for _, tt := range tests {
+		got, err := parseSweepSelection(tt.input, 5)
+		if err != nil {
+			t.Errorf("parseSweepSelection(%q): %v", tt.input, err)
+			continue
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("parseSweepSelection(%q) = %v, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"0", "6", "2-1", "x", "1-"} {
+		if _, err := parseSweepSelection(input, 5); err == nil {
+			t.Errorf("parseSweepSelection(%q) should fail", input)
+		}
+	}
+}
+
+func TestPrintTeardownReport(t *testing.T) {
+	var buf bytes.Buffer
+	printTeardownReport(&buf, &cluster.TeardownReport{
+		ClusterName: "prod",
+		ClusterType: cluster.ClusterTypeEKS,
+		Resources: []cluster.TeardownResource{
+			{Type: "node-group", ID: "ng-1", Name: "ng-1", Detail: "3 nodes"},
+			{Type: "volume", ID: "vol-1", Detail: "PVC db/data-pg-0", Retained: true},
+		},
+		Warnings: []string{"1 resources outlive the cluster"},
+	})
+
+	out := buf.String()
+	for _, want := range []string{"removes 1 resources", "ng-1", "NOT removed (1)", "vol-1", "PVC db/data-pg-0", "nothing was deleted"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestK8sDeleteHasDryRunFlag(t *testing.T) {
+	if k8sDeleteCmd.Flags().Lookup("dry-run") == nil {
+		t.Error("k8s delete is missing --dry-run")
+	}
+}
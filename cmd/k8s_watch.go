@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	watchColorReset  = "\033[0m"
+	watchColorGreen  = "\033[32m"
+	watchColorYellow = "\033[33m"
+	watchColorRed    = "\033[31m"
+	watchClearScreen = "\033[H\033[2J"
+)
+
+var (
+	k8sWatchInterval   time.Duration
+	k8sWatchKubeconfig string
+	k8sWatchContext    string
+	k8sWatchAllNS      bool
+	k8sWatchNoColor    bool
+	k8sAskWatch        bool
+)
+
+var k8sWatchCmd = &cobra.Command{
+	Use:   "watch [resource | question]",
+	Short: "Poll a resource listing and highlight changes until interrupted",
+	Long: `Keep polling kubectl get for a resource and re-render the table on every
+tick. Rows that appeared are green, rows whose columns changed are yellow, and
+rows that disappeared are red. Press Ctrl+C to stop.
+
+Accepts either a kubectl resource name or a natural language request.
+
+Examples:
+  clanker k8s watch pods -n foo
+  clanker k8s watch "watch pods in namespace foo"
+  clanker k8s watch deployments -A --interval 5s`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, ok := k8s.ParseWatchQuery(args[0])
+		if !ok {
+			opts = k8s.WatchOptions{Resource: strings.TrimSpace(args[0])}
+		}
+		if k8sNamespace != "" {
+			opts.Namespace = k8sNamespace
+		}
+		if k8sWatchAllNS {
+			opts.AllNamespaces = true
+		}
+		opts.Interval = k8sWatchInterval
+
+		client := k8s.NewClient(k8sWatchKubeconfig, k8sWatchContext, viper.GetBool("debug"))
+		return runK8sWatch(cmd.Context(), client, opts, !k8sWatchNoColor)
+	},
+}
+
+func init() {
+	k8sCmd.AddCommand(k8sWatchCmd)
+
+	k8sWatchCmd.Flags().DurationVar(&k8sWatchInterval, "interval", k8s.DefaultWatchInterval, "Polling interval")
+	k8sWatchCmd.Flags().StringVarP(&k8sNamespace, "namespace", "n", "", "Namespace to watch")
+	k8sWatchCmd.Flags().BoolVarP(&k8sWatchAllNS, "all-namespaces", "A", false, "Watch across all namespaces")
+	k8sWatchCmd.Flags().StringVar(&k8sWatchKubeconfig, "kubeconfig", "", "Path to kubeconfig (default: ~/.kube/config)")
+	k8sWatchCmd.Flags().StringVar(&k8sWatchContext, "context", "", "kubectl context to use")
+	k8sWatchCmd.Flags().BoolVar(&k8sWatchNoColor, "no-color", false, "Disable diff highlighting")
+
+	k8sAskCmd.Flags().BoolVar(&k8sAskWatch, "watch", false, "Keep polling read-only listings (e.g. \"watch pods in namespace foo\") until interrupted")
+}
+
+// runK8sWatch drives the poll loop until the user interrupts it.
+func runK8sWatch(parent context.Context, client *k8s.Client, opts k8s.WatchOptions, color bool) error {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return client.Watch(ctx, opts, func(frame k8s.WatchFrame) {
+		fmt.Fprint(os.Stdout, watchClearScreen)
+		renderK8sWatchFrame(os.Stdout, opts, frame, color)
+	})
+}
+
+func renderK8sWatchFrame(out io.Writer, opts k8s.WatchOptions, frame k8s.WatchFrame, color bool) {
+	scope := opts.Namespace
+	switch {
+	case opts.AllNamespaces:
+		scope = "all namespaces"
+	case scope == "":
+		scope = "current namespace"
+	}
+	fmt.Fprintf(out, "Every %s: kubectl get %s (%s)  #%d  %s\n\n",
+		watchIntervalLabel(opts.Interval), opts.Resource, scope, frame.Sequence, frame.Timestamp.Format("15:04:05"))
+
+	if frame.Error != nil {
+		fmt.Fprintf(out, "Error: %v\n", frame.Error)
+		return
+	}
+	if frame.Header == "" {
+		fmt.Fprintf(out, "No %s found.\n", opts.Resource)
+		return
+	}
+
+	fmt.Fprintf(out, "  %s\n", frame.Header)
+	for _, line := range frame.Lines {
+		marker, colorCode := " ", ""
+		switch line.Status {
+		case k8s.WatchLineAdded:
+			marker, colorCode = "+", watchColorGreen
+		case k8s.WatchLineChanged:
+			marker, colorCode = "~", watchColorYellow
+		case k8s.WatchLineRemoved:
+			marker, colorCode = "-", watchColorRed
+		}
+		if color && colorCode != "" {
+			fmt.Fprintf(out, "%s%s %s%s\n", colorCode, marker, line.Text, watchColorReset)
+			continue
+		}
+		fmt.Fprintf(out, "%s %s\n", marker, line.Text)
+	}
+}
+
+func watchIntervalLabel(interval time.Duration) string {
+	if interval <= 0 {
+		interval = k8s.DefaultWatchInterval
+	}
+	return interval.String()
+}
@@ -72,6 +72,8 @@ func runWorkloadsAudit(cmd *cobra.Command, args []string) error {
 
 	report.Issues = filterIssuesBySeverity(report.Issues, workloadsAuditSeverity)
 
+	runReportHooks("k8s workloads audit", report)
+
 	switch strings.ToLower(workloadsAuditOutput) {
 	case "json":
 		enc := json.NewEncoder(os.Stdout)
@@ -13,7 +13,6 @@ import (
 
 	"github.com/bgdnvk/clanker/internal/logs"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 // errCollectLimit stops collection once the chat cap is reached (a deliberate
@@ -204,7 +203,7 @@ func runLogsChat(ctx context.Context, opts logs.Options, question, aiProfile str
 	// Override the AI provider for this run if requested (createAIClient reads
 	// ai.default_provider when no per-command profile is set).
 	if strings.TrimSpace(aiProfile) != "" {
-		viper.Set("ai.default_provider", strings.TrimSpace(aiProfile))
+		setFlagOverride("ai.default_provider", strings.TrimSpace(aiProfile))
 	}
 
 	logs.EmitProgress("collect", fmt.Sprintf("collecting %s logs for analysis", opts.Provider))
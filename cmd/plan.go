@@ -0,0 +1,444 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+	"github.com/bgdnvk/clanker/internal/planstore"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	planDriftOutput     string
+	planDriftReapply    bool
+	planDriftUpdate     bool
+	planDriftKubeconfig string
+	planDriftContext    string
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "List, inspect, apply and remove stored plans",
+	Long: `Manage the plans clanker generates.
+
+Plans produced by clanker ask --maker and clanker ask k8s are saved under
+~/.clanker/plans with an ID and a status (pending, applied or failed), so they
+can be applied later without copying JSON around.
+
+Every successful clanker k8s deploy and clanker k8s apply is also stored under
+~/.clanker/plans/applied so it can be compared against live cluster state with
+clanker plan drift.
+
+Examples:
+  clanker plan list
+  clanker plan show 20261015-093000-3f9a
+  clanker plan apply 20261015-093000-3f9a
+  clanker plan cleanup 20261015-093000-3f9a
+  clanker plan rm 20261015-093000-3f9a`,
+}
+
+var planListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stored plans, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		status, _ := cmd.Flags().GetString("status")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		plans, err := planstore.List()
+		if err != nil {
+			return err
+		}
+		plans = filterStoredPlans(plans, status)
+
+		if asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(plans)
+		}
+		if len(plans) == 0 {
+			fmt.Println("No stored plans.")
+			return nil
+		}
+		printStoredPlans(os.Stdout, plans)
+		return nil
+	},
+}
+
+var planShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a stored plan (an unambiguous id prefix is enough)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := planstore.Load(args[0])
+		if err != nil {
+			return err
+		}
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(p)
+		}
+		printStoredPlan(os.Stdout, p)
+		return nil
+	},
+}
+
+var planApplyCmd = &cobra.Command{
+	Use:   "apply <id>",
+	Short: "Apply a stored plan",
+	Long: `Apply a stored plan exactly as clanker ask --apply would, then record
+whether it succeeded in the plan's status.
+
+Before anything runs, the resources the plan references are re-resolved. If
+they were deleted or changed since the plan was generated the apply stops;
+use --regenerate to build a fresh plan from the same question, or
+--ignore-drift to apply anyway. Plans older than --max-plan-age (config:
+plans.max_age, default 24h) get a warning.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := planstore.Load(args[0])
+		if err != nil {
+			return err
+		}
+		settings := map[string]string{"apply": "true", "plan-id": p.ID}
+		cmd.Flags().Visit(func(f *pflag.Flag) {
+			settings[f.Name] = f.Value.String()
+		})
+		return runAskWith(settings)
+	},
+}
+
+var planRmCmd = &cobra.Command{
+	Use:     "rm <id>...",
+	Aliases: []string{"delete"},
+	Short:   "Remove stored plans",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, id := range args {
+			if err := planstore.Delete(id); err != nil {
+				return err
+			}
+			fmt.Printf("Removed plan %s\n", id)
+		}
+		return nil
+	},
+}
+
+var planDriftCmd = &cobra.Command{
+	Use:   "drift [plan-id]",
+	Short: "Report fields that diverged between an applied plan and the live cluster",
+	Long: `Re-fetch every object in a previously applied plan and report fields that
+have drifted from the plan's manifests: image tags, replica counts, and
+container env vars. Objects that no longer exist are reported as missing.
+
+Use --reapply to push the recorded manifests back to the cluster, or --update
+to accept the live values as the new plan baseline.
+
+Examples:
+  clanker plan drift
+  clanker plan drift 20260101-120000-api
+  clanker plan drift 20260101-120000-api --reapply
+  clanker plan drift 20260101-120000-api --update -o json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPlanDrift,
+}
+
+var planCleanupCmd = &cobra.Command{
+	Use:   "cleanup <id>",
+	Short: "Delete every Kubernetes object a plan applied",
+	Long: `Find the objects labelled clanker/plan-id=<id> in every namespace and
+delete them, namespaced objects first and namespaces last. Stored K8s plans
+label what they apply, so this removes what the plan created even after the
+plan itself was removed from the store. Objects a plan created imperatively,
+such as kubectl create secret, carry no label and are left alone.
+
+Examples:
+  clanker plan cleanup 20261015-093000-3f9a
+  clanker plan cleanup 20261015-093000-3f9a --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPlanCleanup,
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.AddCommand(planListCmd)
+	planCmd.AddCommand(planShowCmd)
+	planCmd.AddCommand(planApplyCmd)
+	planCmd.AddCommand(planRmCmd)
+	planCmd.AddCommand(planDriftCmd)
+	planCmd.AddCommand(planCleanupCmd)
+
+	planListCmd.Flags().String("status", "", "Only show plans with this status (pending, applied, failed)")
+	planListCmd.Flags().Bool("json", false, "Output plans as JSON")
+	planShowCmd.Flags().Bool("json", false, "Output the stored plan and its metadata as JSON")
+
+	// Forwarded to ask --apply
+	planApplyCmd.Flags().String("profile", "", "AWS profile to use for execution")
+	planApplyCmd.Flags().String("gcp-project", "", "GCP project ID to use for execution")
+	planApplyCmd.Flags().String("azure-subscription", "", "Azure subscription ID to use for execution")
+	planApplyCmd.Flags().Bool("destroyer", false, "Allow destructive operations")
+	planApplyCmd.Flags().String("as", "", "Apply K8s plans impersonating this user or service account (sa:<namespace>/<name>)")
+	planApplyCmd.Flags().Bool("force", false, "Apply K8s plan manifests even when they fail schema validation or server-side dry-run")
+	planApplyCmd.Flags().Bool("override-policy", false, "Apply K8s plans that violate ~/.clanker/policy.yaml; the override is recorded in ~/.clanker/audit.log")
+	planApplyCmd.Flags().Bool("allow-vulnerable", false, "Apply K8s plans whose images exceed kubernetes.image_scan.max_critical critical CVEs; recorded in ~/.clanker/audit.log")
+	planApplyCmd.Flags().Bool("prune", false, "Delete objects labelled with this plan's ID that it no longer applies")
+	planApplyCmd.Flags().String("replaces", "", "Delete objects the given earlier plan applied that this one does not")
+	planApplyCmd.Flags().Bool("ignore-drift", false, "Run the plan even when resources it references were deleted or changed since it was generated")
+	planApplyCmd.Flags().Bool("regenerate", false, "Regenerate a plan that drifted from live state instead of applying it")
+	planApplyCmd.Flags().Duration("max-plan-age", 0, "Warn when the plan is older than this (default plans.max_age or 24h; 0 disables)")
+	planApplyCmd.Flags().String("emit", "", "Write the plan as code instead of running it (supported: terraform)")
+	planApplyCmd.Flags().String("emit-dir", defaultEmitDir, "Directory for --emit output; must not already contain the generated files")
+
+	planCleanupCmd.Flags().Bool("yes", false, "Delete without asking for confirmation")
+
+	planDriftCmd.Flags().StringVarP(&planDriftOutput, "output", "o", "table", "Output format (table, json)")
+	planDriftCmd.Flags().BoolVar(&planDriftReapply, "reapply", false, "Re-apply the recorded manifests to revert drift")
+	planDriftCmd.Flags().BoolVar(&planDriftUpdate, "update", false, "Accept live values as the new plan baseline")
+	planDriftCmd.Flags().StringVar(&planDriftKubeconfig, "kubeconfig", "", "Path to kubeconfig (default: ~/.kube/config)")
+	planDriftCmd.Flags().StringVar(&planDriftContext, "context", "", "kubectl context to use (default: the context recorded with the plan)")
+}
+
+func runPlanDrift(cmd *cobra.Command, args []string) error {
+	if planDriftReapply && planDriftUpdate {
+		return fmt.Errorf("use only one of --reapply or --update")
+	}
+
+	if len(args) == 0 {
+		ids, err := plan.ListAppliedPlans()
+		if err != nil {
+			return fmt.Errorf("list applied plans: %w", err)
+		}
+		if len(ids) == 0 {
+			fmt.Println("No applied plans recorded yet.")
+			return nil
+		}
+		fmt.Println("Applied plans:")
+		for _, id := range ids {
+			fmt.Printf("  %s\n", id)
+		}
+		return nil
+	}
+
+	applied, err := plan.LoadAppliedPlan(args[0])
+	if err != nil {
+		return err
+	}
+
+	kubeContext := planDriftContext
+	if kubeContext == "" {
+		kubeContext = applied.Context
+	}
+	ctx := context.Background()
+	client := k8s.NewClient(planDriftKubeconfig, kubeContext, viper.GetBool("debug"))
+
+	report, err := plan.DetectDrift(ctx, applied, appliedPlanLiveFetcher(client))
+	if err != nil {
+		return fmt.Errorf("drift detection failed: %w", err)
+	}
+
+	switch {
+	case planDriftReapply && report.HasDrift():
+		output, err := client.Apply(ctx, applied.CombinedManifest(), applied.Namespace)
+		if err != nil {
+			return fmt.Errorf("re-apply failed: %w", err)
+		}
+		fmt.Print(output)
+	case planDriftUpdate && len(report.Drifted) > 0:
+		if err := plan.UpdateFromLive(applied, report); err != nil {
+			return fmt.Errorf("update plan: %w", err)
+		}
+		if _, err := plan.SaveAppliedPlan(applied); err != nil {
+			return err
+		}
+	}
+
+	runReportHooks("plan drift", report)
+
+	if strings.ToLower(planDriftOutput) == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printPlanDriftReport(os.Stdout, report)
+	switch {
+	case planDriftReapply && report.HasDrift():
+		fmt.Println("\nRe-applied recorded manifests.")
+	case planDriftUpdate && len(report.Drifted) > 0:
+		fmt.Println("\nPlan baseline updated to live values.")
+	case report.HasDrift():
+		fmt.Printf("\nRun with --reapply to revert, or --update to accept the live values.\n")
+	}
+	return nil
+}
+
+// appliedPlanLiveFetcher adapts kubectl get -o json to plan.LiveFetcher,
+// mapping NotFound to a nil object.
+func appliedPlanLiveFetcher(client *k8s.Client) plan.LiveFetcher {
+	return func(ctx context.Context, kind, name, namespace string) ([]byte, error) {
+		data, err := client.GetJSON(ctx, strings.ToLower(kind), name, namespace)
+		if err != nil {
+			if strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "not found") {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return data, nil
+	}
+}
+
+func printPlanDriftReport(out io.Writer, report *plan.DriftReport) {
+	fmt.Fprintf(out, "Plan %s: checked %d object(s)\n", report.PlanID, report.Checked)
+	for _, msg := range report.FetchErrs {
+		fmt.Fprintf(out, "Warning: %s\n", msg)
+	}
+
+	if !report.HasDrift() {
+		fmt.Fprintln(out, "No drift detected. ✓")
+		return
+	}
+
+	for _, ref := range report.Missing {
+		fmt.Fprintf(out, "Missing: %s\n", ref)
+	}
+	if len(report.Drifted) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "%d drifted field(s):\n", len(report.Drifted))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OBJECT\tFIELD\tPLAN\tLIVE")
+	fmt.Fprintln(w, "------\t-----\t----\t----")
+	for _, d := range report.Drifted {
+		obj := fmt.Sprintf("%s/%s", strings.ToLower(d.Kind), d.Name)
+		if d.Namespace != "" {
+			obj = d.Namespace + "/" + obj
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", obj, d.Field, truncate(d.Expected, 40), truncate(d.Live, 40))
+	}
+	w.Flush()
+}
+
+// recordAppliedPlan stores a successful apply for later drift checks.
+// Failures are reported but never fail the apply itself.
+func recordAppliedPlan(source, summary, kubeContext, namespace, manifest string) {
+	applied, err := plan.NewAppliedPlan(source, summary, kubeContext, namespace, manifest)
+	if err != nil {
+		if viper.GetBool("debug") {
+			fmt.Printf("[plan] not recording applied plan: %v\n", err)
+		}
+		return
+	}
+	if _, err := plan.SaveAppliedPlan(applied); err != nil {
+		fmt.Fprintf(os.Stderr, "[plan] warning: could not record applied plan: %v\n", err)
+		return
+	}
+	fmt.Printf("Recorded as plan %s (check later with: clanker plan drift %s)\n", applied.ID, applied.ID)
+}
+
+// saveGeneratedPlan stores a plan that was just printed so it can be applied
+// later with clanker plan apply. The note goes to stderr to keep the plan
+// JSON on stdout pipeable; failures never fail plan generation.
+func saveGeneratedPlan(kind, provider, source, question, summary string, planJSON []byte) {
+	p, err := planstore.Add(kind, provider, source, question, summary, planJSON)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[plan] warning: could not save plan: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Saved as plan %s (apply with: clanker plan apply %s)\n", p.ID, p.ID)
+}
+
+// trackStoredPlanApply finds the stored copy of a plan being applied, by ID
+// or else by content, and marks it started. The returned func records the
+// outcome; it is a no-op when the plan is not in the store.
+func trackStoredPlanApply(planID string, rawPlan []byte) func(error) {
+	var (
+		p   *planstore.Plan
+		err error
+	)
+	if planID != "" {
+		p, err = planstore.Load(planID)
+	} else {
+		p, err = planstore.FindByBody(rawPlan)
+	}
+	if err != nil || p == nil {
+		return func(error) {}
+	}
+	if err := planstore.MarkStarted(p); err != nil {
+		fmt.Fprintf(os.Stderr, "[plan] warning: could not update plan %s: %v\n", p.ID, err)
+	}
+	return func(applyErr error) {
+		if err := planstore.MarkFinished(p, applyErr); err != nil {
+			fmt.Fprintf(os.Stderr, "[plan] warning: could not update plan %s: %v\n", p.ID, err)
+		}
+	}
+}
+
+// filterStoredPlans keeps plans with the given status; empty keeps all
+func filterStoredPlans(plans []*planstore.Plan, status string) []*planstore.Plan {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if status == "" {
+		return plans
+	}
+	var out []*planstore.Plan
+	for _, p := range plans {
+		if string(p.Status) == status {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func printStoredPlans(w io.Writer, plans []*planstore.Plan) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tKIND\tPROVIDER\tSTATUS\tSUMMARY")
+	for _, p := range plans {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			p.ID, p.CreatedAt.Local().Format("2006-01-02 15:04:05"), p.Kind,
+			orDash(p.Provider), p.Status, truncate(orDash(p.Summary), 60))
+	}
+	tw.Flush()
+}
+
+func printStoredPlan(w io.Writer, p *planstore.Plan) {
+	fmt.Fprintf(w, "ID:        %s\n", p.ID)
+	fmt.Fprintf(w, "Kind:      %s\n", p.Kind)
+	if p.Provider != "" {
+		fmt.Fprintf(w, "Provider:  %s\n", p.Provider)
+	}
+	fmt.Fprintf(w, "Source:    %s\n", p.Source)
+	if p.Question != "" {
+		fmt.Fprintf(w, "Question:  %s\n", p.Question)
+	}
+	if p.Summary != "" {
+		fmt.Fprintf(w, "Summary:   %s\n", p.Summary)
+	}
+	fmt.Fprintf(w, "Status:    %s\n", p.Status)
+	fmt.Fprintf(w, "Created:   %s\n", p.CreatedAt.Local().Format("2006-01-02 15:04:05 MST"))
+	if p.AppliedAt != nil {
+		fmt.Fprintf(w, "Applied:   %s\n", p.AppliedAt.Local().Format("2006-01-02 15:04:05 MST"))
+	}
+	if p.FinishedAt != nil {
+		fmt.Fprintf(w, "Finished:  %s\n", p.FinishedAt.Local().Format("2006-01-02 15:04:05 MST"))
+	}
+	if p.Error != "" {
+		fmt.Fprintf(w, "Error:     %s\n", p.Error)
+	}
+
+	var body bytes.Buffer
+	if err := json.Indent(&body, p.Body, "", "  "); err != nil {
+		body.Reset()
+		body.Write(p.Body)
+	}
+	fmt.Fprintf(w, "\n%s\n", body.String())
+}
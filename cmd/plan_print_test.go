@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/planstore"
+)
+
+func TestPrintStoredPlan(t *testing.T) {
+	applied := time.Date(2026, 10, 15, 9, 31, 0, 0, time.UTC)
+	var buf bytes.Buffer
+	printStoredPlan(&buf, &planstore.Plan{
+		ID:         "20261015-093000-3f9a",
+		Kind:       planstore.KindMaker,
+		Provider:   "aws",
+		Source:     "ask --maker",
+		Question:   "create a bucket",
+		Status:     planstore.StatusFailed,
+		CreatedAt:  applied.Add(-time.Minute),
+		AppliedAt:  &applied,
+		FinishedAt: &applied,
+		Error:      "aws command failed",
+		Body:       []byte(`{"provider":"aws"}`),
+	})
+	out := buf.String()
+	for _, want := range []string{"ID:        20261015-093000-3f9a", "Status:    failed", "Error:     aws command failed", "Applied:", "\"provider\": \"aws\""} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestFilterStoredPlans(t *testing.T) {
+	plans := []*planstore.Plan{
+		{ID: "a", Status: planstore.StatusPending},
+		{ID: "b", Status: planstore.StatusApplied},
+		{ID: "c", Status: planstore.StatusPending},
+	}
+	if got := filterStoredPlans(plans, ""); len(got) != 3 {
+		t.Errorf("no filter = %d plans", len(got))
+	}
+	if got := filterStoredPlans(plans, "Pending"); len(got) != 2 || got[1].ID != "c" {
+		t.Errorf("pending = %v", got)
+	}
+}
+
+func TestTrackStoredPlanApply(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p, err := planstore.Add(planstore.KindMaker, "aws", "ask --maker", "", "", []byte(`{"provider":"aws"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Plans applied from a file are matched by content
+	trackStoredPlanApply("", []byte("{\"provider\": \"aws\"}\n"))(errors.New("boom"))
+	if got, _ := planstore.Load(p.ID); got.Status != planstore.StatusFailed {
+		t.Errorf("status = %s, want failed", got.Status)
+	}
+
+	trackStoredPlanApply(p.ID, nil)(nil)
+	if got, _ := planstore.Load(p.ID); got.Status != planstore.StatusApplied {
+		t.Errorf("status = %s, want applied", got.Status)
+	}
+
+	// Unknown plans are left alone
+	trackStoredPlanApply("", []byte(`{"provider":"gcp"}`))(nil)
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/maker"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// defaultPlanMaxAge is how old a plan may be before apply warns that the
+// resources it was generated against may have changed
+const defaultPlanMaxAge = 24 * time.Hour
+
+// planRevalidation controls the checks ask --apply runs before executing a
+// plan that was generated earlier
+type planRevalidation struct {
+	MaxAge      time.Duration
+	IgnoreDrift bool
+	Regenerate  bool
+}
+
+func planRevalidationFromFlags(cmd *cobra.Command) planRevalidation {
+	ignoreDrift, _ := cmd.Flags().GetBool("ignore-drift")
+	regenerate, _ := cmd.Flags().GetBool("regenerate")
+	return planRevalidation{
+		MaxAge:      resolvePlanMaxAge(cmd),
+		IgnoreDrift: ignoreDrift,
+		Regenerate:  regenerate,
+	}
+}
+
+// resolvePlanMaxAge returns --max-plan-age, then plans.max_age from the
+// config, then the default. Zero disables the staleness warning.
+func resolvePlanMaxAge(cmd *cobra.Command) time.Duration {
+	if cmd.Flags().Changed("max-plan-age") {
+		maxAge, _ := cmd.Flags().GetDuration("max-plan-age")
+		return maxAge
+	}
+	if raw := strings.TrimSpace(viper.GetString("plans.max_age")); raw != "" {
+		maxAge, err := time.ParseDuration(raw)
+		if err == nil {
+			return maxAge
+		}
+		fmt.Fprintf(os.Stderr, "[plan] warning: ignoring invalid plans.max_age %q: %v\n", raw, err)
+	}
+	return defaultPlanMaxAge
+}
+
+// stalePlanWarning describes a plan older than maxAge, or returns "" when it
+// is fresh, undated, or the check is disabled
+func stalePlanWarning(createdAt time.Time, maxAge time.Duration, now time.Time) string {
+	if maxAge <= 0 || createdAt.IsZero() {
+		return ""
+	}
+	age := now.Sub(createdAt)
+	if age <= maxAge {
+		return ""
+	}
+	return fmt.Sprintf("plan was generated %s ago (older than %s); resources it references may have changed",
+		age.Round(time.Minute), maxAge)
+}
+
+func warnIfStalePlan(createdAt time.Time, maxAge time.Duration) {
+	if msg := stalePlanWarning(createdAt, maxAge, time.Now()); msg != "" {
+		fmt.Fprintf(os.Stderr, "[plan] warning: %s\n", msg)
+	}
+}
+
+// revalidateAWSMakerPlan re-resolves the EC2 resources an AWS plan references
+// before it runs. Drift stops the apply unless it is ignored; with
+// --regenerate a fresh plan is generated from the plan's question instead.
+func revalidateAWSMakerPlan(ctx context.Context, cmd *cobra.Command, p *maker.Plan, opts maker.ExecOptions, reval planRevalidation) error {
+	drifts, warnings := maker.RevalidateAWSPlan(ctx, p, opts, nil)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "[plan] warning: %s\n", w)
+	}
+	if len(drifts) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(drifts))
+	for _, d := range drifts {
+		lines = append(lines, d.String())
+	}
+	if reval.IgnoreDrift {
+		for _, line := range lines {
+			fmt.Fprintf(os.Stderr, "[plan] warning: applying despite drift: %s\n", line)
+		}
+		return nil
+	}
+	if reval.Regenerate {
+		fmt.Fprintf(os.Stderr, "[plan] plan drifted, regenerating:\n  - %s\n", strings.Join(lines, "\n  - "))
+		if err := regenerateMakerPlan(cmd, p.Question); err != nil {
+			return err
+		}
+		return fmt.Errorf("plan drifted since it was generated; review the regenerated plan above and apply it instead")
+	}
+	return fmt.Errorf("resources referenced by the plan changed since it was generated:\n  - %s\nregenerate the plan (--regenerate), or apply anyway with --ignore-drift", strings.Join(lines, "\n  - "))
+}
+
+// regenerateMakerPlan runs ask --maker again with the stale plan's question
+func regenerateMakerPlan(cmd *cobra.Command, question string) error {
+	if strings.TrimSpace(question) == "" {
+		return fmt.Errorf("plan records no question to regenerate it from")
+	}
+	for name, value := range map[string]string{"apply": "false", "maker": "true", "plan-id": "", "plan-file": ""} {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return err
+		}
+	}
+	return cmd.RunE(cmd, []string{question})
+}
+
+// revalidateK8sAgentPlan checks that the objects a K8s agent plan changes
+// were not deleted, rescaled or modified since it was generated
+func revalidateK8sAgentPlan(ctx context.Context, p *k8s.K8sPlan, reval planRevalidation, debug bool) error {
+	client := k8s.NewClient("", "", debug)
+	drifts, warnings := k8s.RevalidatePlan(ctx, p, "", k8s.LiveObjectFetcher(client))
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "[plan] warning: %s\n", w)
+	}
+	if len(drifts) == 0 {
+		return nil
+	}
+	if reval.IgnoreDrift {
+		for _, d := range drifts {
+			fmt.Fprintf(os.Stderr, "[plan] warning: applying despite drift: %s\n", d)
+		}
+		return nil
+	}
+	if reval.Regenerate {
+		if strings.TrimSpace(p.Question) == "" {
+			return fmt.Errorf("plan records no question to regenerate it from")
+		}
+		for _, d := range drifts {
+			fmt.Fprintf(os.Stderr, "[plan] drift: %s\n", d)
+		}
+		fmt.Fprintln(os.Stderr, "[plan] regenerating the plan against the current cluster state")
+		if err := handleK8sQuery(ctx, p.Question, debug, ""); err != nil {
+			return err
+		}
+		return fmt.Errorf("plan drifted since it was generated; review the regenerated plan above and apply it instead")
+	}
+	return k8s.DriftError(drifts)
+}
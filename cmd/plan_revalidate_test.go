@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestStalePlanWarning(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	if msg := stalePlanWarning(now.Add(-2*time.Hour), 24*time.Hour, now); msg != "" {
+		t.Errorf("fresh plan warned: %q", msg)
+	}
+	if msg := stalePlanWarning(time.Time{}, 24*time.Hour, now); msg != "" {
+		t.Errorf("undated plan warned: %q", msg)
+	}
+	if msg := stalePlanWarning(now.Add(-72*time.Hour), 0, now); msg != "" {
+		t.Errorf("disabled check warned: %q", msg)
+	}
+	msg := stalePlanWarning(now.Add(-50*time.Hour), 24*time.Hour, now)
+	if !strings.Contains(msg, "generated 50h0m0s ago") || !strings.Contains(msg, "older than 24h0m0s") {
+		t.Errorf("stale warning = %q", msg)
+	}
+}
+
+func TestResolvePlanMaxAge(t *testing.T) {
+	newCmd := func() *cobra.Command {
+		c := &cobra.Command{Use: "apply"}
+		c.Flags().Duration("max-plan-age", 0, "")
+		return c
+	}
+	t.Cleanup(func() { viper.Set("plans.max_age", nil) })
+
+	if got := resolvePlanMaxAge(newCmd()); got != defaultPlanMaxAge {
+		t.Errorf("default = %s", got)
+	}
+	viper.Set("plans.max_age", "6h")
+	if got := resolvePlanMaxAge(newCmd()); got != 6*time.Hour {
+		t.Errorf("config = %s", got)
+	}
+	c := newCmd()
+	if err := c.Flags().Set("max-plan-age", "0"); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolvePlanMaxAge(c); got != 0 {
+		t.Errorf("flag = %s", got)
+	}
+}
@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/dryrun"
+	"github.com/bgdnvk/clanker/internal/maker"
+	"github.com/bgdnvk/clanker/internal/planstore"
+	"github.com/bgdnvk/clanker/internal/plugin"
+	"github.com/bgdnvk/clanker/internal/routing"
+	"github.com/spf13/cobra"
+)
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Manage third-party agent plugins",
+	Long: `Plugins are agents other teams ship as separate binaries, such as one for
+Datadog or Snowflake. clanker starts a plugin when a question routes to it
+and talks to it over gRPC (see internal/plugin/agent.proto).
+
+Plugins are the executables named clanker-plugin-<name> in ~/.clanker/plugins
+(or plugins_dir), and the ones listed in the config file:
+
+  plugins:
+    - name: datadog
+      path: ~/bin/clanker-datadog
+      keywords: [datadog, monitor, dashboard]
+
+Questions containing a plugin's keywords go to it, and ask --maker asks it for
+the plan. Keywords set in the config are used as is; otherwise clanker starts
+the plugin to ask for them. routing.force and clanker route correct accept
+plugin names.`,
+}
+
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List installed plugins and the keywords routed to them",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		routes := pluginRoutes()
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(routes)
+		}
+		if len(routes) == 0 {
+			fmt.Println("No plugins installed.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tVERSION\tKEYWORDS\tPATH")
+		for _, r := range routes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Config.Name, orDash(r.Info.Version), orDash(strings.Join(r.Info.Keywords, ", ")), r.Config.Path)
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pluginCmd)
+	pluginCmd.AddCommand(pluginListCmd)
+	pluginListCmd.Flags().Bool("json", false, "Output plugins as JSON")
+}
+
+// pluginRoute is an installed plugin and what it routes on
+type pluginRoute struct {
+	Config plugin.Config `json:"config"`
+	Info   plugin.Info   `json:"info"`
+}
+
+// pluginDescribeTimeout bounds starting a plugin to ask for its keywords
+const pluginDescribeTimeout = 15 * time.Second
+
+var (
+	pluginConfigsOnce sync.Once
+	pluginConfigList  []plugin.Config
+	pluginRoutesOnce  sync.Once
+	pluginRouteList   []pluginRoute
+)
+
+// pluginConfigs returns the installed plugins. Plugins named like a
+// built-in agent are skipped so they cannot take over its questions.
+func pluginConfigs() []plugin.Config {
+	pluginConfigsOnce.Do(func() {
+		configs, err := plugin.FromConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[plugin] warning: %v\n", err)
+			return
+		}
+		for _, c := range configs {
+			if _, builtin := pinnableAgents[c.Name]; builtin {
+				fmt.Fprintf(os.Stderr, "[plugin] warning: ignoring plugin %s, which is named like a built-in agent\n", c.Name)
+				continue
+			}
+			pluginConfigList = append(pluginConfigList, c)
+		}
+	})
+	return pluginConfigList
+}
+
+// findPlugin returns the installed plugin named name
+func findPlugin(name string) (plugin.Config, bool) {
+	for _, c := range pluginConfigs() {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return plugin.Config{}, false
+}
+
+// pluginRoutes returns the installed plugins with their keywords, starting
+// the ones whose keywords are not in the config to ask for them. A plugin
+// that fails to start is left out of routing.
+func pluginRoutes() []pluginRoute {
+	pluginRoutesOnce.Do(func() {
+		for _, c := range pluginConfigs() {
+			route := pluginRoute{Config: c, Info: plugin.Info{Name: c.Name, Keywords: c.Keywords}}
+			if len(c.Keywords) == 0 {
+				info, err := describePlugin(c)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "[plugin] warning: %v\n", err)
+					continue
+				}
+				route.Info = info
+			}
+			var keywords []string
+			for _, k := range route.Info.Keywords {
+				if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+					keywords = append(keywords, k)
+				}
+			}
+			route.Info.Keywords = keywords
+			pluginRouteList = append(pluginRouteList, route)
+		}
+	})
+	return pluginRouteList
+}
+
+func describePlugin(c plugin.Config) (plugin.Info, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginDescribeTimeout)
+	defer cancel()
+	p, err := plugin.Launch(ctx, c, os.Stderr)
+	if err != nil {
+		return plugin.Info{}, err
+	}
+	defer p.Close()
+	info, err := p.Describe(ctx)
+	if err != nil {
+		return plugin.Info{}, fmt.Errorf("plugin %s: %w", c.Name, err)
+	}
+	return info, nil
+}
+
+// pluginRoutingRules routes questions by plugin keywords. They rank below
+// the explicit Clanker Cloud, Hermes, ticket and AWS audit requests and
+// above every other built-in agent, since a plugin's keywords name its
+// own system.
+func pluginRoutingRules() []routing.Rule {
+	var rules []routing.Rule
+	for _, r := range pluginRoutes() {
+		if len(r.Info.Keywords) == 0 {
+			continue
+		}
+		reason := r.Info.Description
+		if reason == "" {
+			reason = r.Config.Name + " plugin"
+		}
+		rules = append(rules, routing.Rule{Agent: r.Config.Name, Weight: 91, Reason: reason,
+			Match: routing.Keywords(r.Info.Keywords...)})
+	}
+	return rules
+}
+
+// pluginForQuestion returns the plugin a question's keywords route to
+func pluginForQuestion(question string) (plugin.Config, bool) {
+	rules := pluginRoutingRules()
+	if len(rules) == 0 {
+		return plugin.Config{}, false
+	}
+	decision := routing.Score(routeOnlyUserQuestion(question), rules, "", "")
+	if decision.Agent == "" {
+		return plugin.Config{}, false
+	}
+	return findPlugin(decision.Agent)
+}
+
+// handlePluginQuery asks a plugin a question and prints its answer
+func handlePluginQuery(ctx context.Context, c plugin.Config, question string, opts routedAgentOptions) error {
+	p, err := plugin.Launch(ctx, c, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	resp, err := p.HandleQuery(ctx, plugin.QueryRequest{Question: question, Profile: opts.Profile, Debug: opts.Debug})
+	if err != nil {
+		return fmt.Errorf("plugin %s: %w", c.Name, err)
+	}
+	fmt.Println(strings.TrimRight(resp.Answer, "\n"))
+	return nil
+}
+
+// generatePluginPlan asks a plugin for a maker plan, then prints and saves
+// it like any other. The plan's provider names the plugin so ask --apply
+// hands it back to the same plugin.
+func generatePluginPlan(ctx context.Context, c plugin.Config, question string, destroyer, debug bool) error {
+	_, _ = fmt.Fprintf(os.Stderr, "[maker] provider=%s%s (plugin)\n", plugin.ProviderPrefix, c.Name)
+	p, err := plugin.Launch(ctx, c, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	resp, err := p.GeneratePlan(ctx, plugin.PlanRequest{Question: question, Destroyer: destroyer, Debug: debug})
+	if err != nil {
+		return fmt.Errorf("plugin %s: %w", c.Name, err)
+	}
+	plan := resp.Plan
+	if plan == nil || len(plan.Commands) == 0 {
+		return fmt.Errorf("plugin %s returned a plan with no commands", c.Name)
+	}
+	plan.Provider = plugin.ProviderPrefix + c.Name
+	plan.Question = question
+	if plan.CreatedAt.IsZero() {
+		plan.CreatedAt = time.Now().UTC()
+	}
+	if plan.Version == 0 {
+		plan.Version = maker.CurrentPlanVersion
+	}
+
+	out, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	if err := runPlanGeneratedHooks("ask --maker", out); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, plan.Provider, "ask --maker", question, plan.Summary, out)
+	return nil
+}
+
+// pluginPlanName returns the plugin a raw plan belongs to, if a plugin
+// generated it
+func pluginPlanName(rawPlan string) (string, bool) {
+	var head struct {
+		Provider string `json:"provider"`
+	}
+	if err := json.Unmarshal([]byte(rawPlan), &head); err != nil {
+		return "", false
+	}
+	return strings.CutPrefix(strings.TrimSpace(head.Provider), plugin.ProviderPrefix)
+}
+
+// applyPluginPlan hands a plan back to the plugin that generated it
+func applyPluginPlan(ctx context.Context, name, rawPlan string, destroyer, debug bool) error {
+	c, ok := findPlugin(name)
+	if !ok {
+		return fmt.Errorf("plan was made by plugin %s, which is not installed", name)
+	}
+	var plan maker.Plan
+	if err := json.Unmarshal([]byte(rawPlan), &plan); err != nil {
+		return fmt.Errorf("invalid plan: %w", err)
+	}
+	audit.SetPlan(plan.Provider, []byte(rawPlan), plan.Summary)
+	// Plugins are not asked to dry run; print the plan's commands instead
+	if dryrun.Enabled() {
+		for _, c := range plan.Commands {
+			if len(c.Args) > 0 {
+				dryrun.Print(os.Stdout, c.Args[0], c.Args[1:])
+			}
+		}
+		return nil
+	}
+	p, err := plugin.Launch(ctx, c, os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	resp, err := p.ApplyPlan(ctx, plugin.ApplyRequest{Plan: &plan, Destroyer: destroyer, Debug: debug})
+	if resp.Output != "" {
+		fmt.Println(strings.TrimRight(resp.Output, "\n"))
+	}
+	if err != nil {
+		return fmt.Errorf("plugin %s: %w", name, err)
+	}
+	return nil
+}
+
+// resolveAgentName maps a routing.force or route correct value to an
+// agent: a built-in agent or alias, or an installed plugin
+func resolveAgentName(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if agent, ok := pinnableAgents[name]; ok {
+		return agent, true
+	}
+	if _, ok := findPlugin(name); ok {
+		return name, true
+	}
+	return "", false
+}
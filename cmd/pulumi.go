@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	pulumiclient "github.com/bgdnvk/clanker/internal/pulumi"
+	"github.com/spf13/cobra"
+)
+
+var pulumiCmd = &cobra.Command{
+	Use:   "pulumi",
+	Short: "Pulumi stack operations",
+	Long: `Read Pulumi stacks, previews and stack outputs for the projects configured
+under pulumi.projects, or for a project directory given as a path.`,
+}
+
+var pulumiStacksCmd = &cobra.Command{
+	Use:   "stacks [project-or-path]",
+	Short: "List a project's stacks",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, format, err := pulumiClientFromFlags(cmd, args)
+		if err != nil {
+			return err
+		}
+		stacks, err := client.ListStacks(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(format, "json") {
+			return encodePulumiJSON(stacks)
+		}
+		fmt.Printf("Pulumi stacks for %s (%s):\n", client.Project(), client.Path())
+		fmt.Print(pulumiclient.FormatStacks(stacks))
+		return nil
+	},
+}
+
+var pulumiPreviewCmd = &cobra.Command{
+	Use:   "preview [project-or-path]",
+	Short: "Summarize what pulumi preview would change",
+	Long: `Run pulumi preview --json and list the resources it would create, update,
+replace or delete. Nothing is changed.
+
+Examples:
+  clanker pulumi preview api --stack prod
+  clanker pulumi preview ./infra`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, format, err := pulumiClientFromFlags(cmd, args)
+		if err != nil {
+			return err
+		}
+		preview, err := client.Preview(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(format, "json") {
+			return encodePulumiJSON(preview)
+		}
+		fmt.Print(preview.Format())
+		return nil
+	},
+}
+
+var pulumiOutputsCmd = &cobra.Command{
+	Use:   "outputs [project-or-path]",
+	Short: "Show a stack's outputs, with secrets masked",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, format, err := pulumiClientFromFlags(cmd, args)
+		if err != nil {
+			return err
+		}
+		outputs, err := client.Outputs(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(format, "json") {
+			return encodePulumiJSON(outputs)
+		}
+		fmt.Print(pulumiclient.FormatOutputs(outputs))
+		return nil
+	},
+}
+
+func pulumiClientFromFlags(cmd *cobra.Command, args []string) (*pulumiclient.Client, string, error) {
+	project := ""
+	if len(args) > 0 {
+		project = args[0]
+	}
+	stack, _ := cmd.Flags().GetString("stack")
+	format, _ := cmd.Flags().GetString("format")
+	client, err := pulumiclient.NewClient(project, stack)
+	return client, format, err
+}
+
+func encodePulumiJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func init() {
+	pulumiCmd.AddCommand(pulumiStacksCmd, pulumiPreviewCmd, pulumiOutputsCmd)
+	for _, c := range []*cobra.Command{pulumiStacksCmd, pulumiPreviewCmd, pulumiOutputsCmd} {
+		c.Flags().String("format", "text", "Output format: text or json")
+	}
+	for _, c := range []*cobra.Command{pulumiPreviewCmd, pulumiOutputsCmd} {
+		c.Flags().String("stack", "", "Stack to use (default: the project's configured or selected stack)")
+	}
+	rootCmd.AddCommand(pulumiCmd)
+}
@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bgdnvk/clanker/internal/aws/posture"
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/bgdnvk/clanker/internal/linear"
+	"github.com/bgdnvk/clanker/internal/notion"
+	"github.com/bgdnvk/clanker/internal/reportstore"
+	"github.com/bgdnvk/clanker/internal/ticket"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "List and inspect stored diagnostic and audit reports",
+	Long: `Manage the reports clanker generates.
+
+Kubernetes diagnoses from clanker ask and AWS security audits are saved under
+~/.clanker/reports with an ID, so a ticket filed from one can link back to the
+full report.
+
+Examples:
+  clanker report list
+  clanker report show 20261015-093000-3f9a
+  clanker report ticket 20261015-093000-3f9a --tracker linear`,
+}
+
+var reportListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stored reports, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reports, err := reportstore.List()
+		if err != nil {
+			return err
+		}
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(reports)
+		}
+		if len(reports) == 0 {
+			fmt.Println("No stored reports.")
+			return nil
+		}
+		printStoredReports(os.Stdout, reports)
+		return nil
+	},
+}
+
+var reportShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a stored report (an unambiguous id prefix is enough)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := reportstore.Load(args[0])
+		if err != nil {
+			return err
+		}
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(r)
+		}
+		printStoredReport(os.Stdout, r)
+		return nil
+	},
+}
+
+var reportTicketCmd = &cobra.Command{
+	Use:   "ticket [id]",
+	Short: "File a stored report as a Linear issue or Notion page (default: the latest report)",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		debug := viper.GetBool("debug")
+		tracker, _ := cmd.Flags().GetString("tracker")
+		tracker, err := ticket.ParseTracker(tracker)
+		if err != nil {
+			return err
+		}
+		var r *reportstore.Report
+		if len(args) == 1 {
+			r, err = reportstore.Load(args[0])
+		} else {
+			r, err = latestReport()
+		}
+		if err != nil {
+			return err
+		}
+		return fileReportTicket(cmd.Context(), r, tracker, debug)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.AddCommand(reportListCmd)
+	reportCmd.AddCommand(reportShowCmd)
+	reportCmd.AddCommand(reportTicketCmd)
+
+	reportListCmd.Flags().Bool("json", false, "Output reports as JSON")
+	reportShowCmd.Flags().Bool("json", false, "Output the stored report and its metadata as JSON")
+	reportTicketCmd.Flags().String("tracker", "", "Tracker to file into: linear or notion (default: whichever is configured)")
+}
+
+// saveReport stores a report and returns it, or nil when it could not be
+// saved; a report that fails to save is still printed
+func saveReport(kind, source, question, summary, text string, body any) *reportstore.Report {
+	r, err := reportstore.Add(kind, source, question, summary, text, body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[report] warning: could not save report: %v\n", err)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Saved as report %s (file a ticket with: clanker report ticket %s)\n", r.ID, r.ID)
+	return r
+}
+
+// fileRequestedTicket files r when ask was run with --create-ticket
+func fileRequestedTicket(ctx context.Context, r *reportstore.Report, debug bool) error {
+	tracker := viper.GetString("ask.create_ticket")
+	if tracker == "" || r == nil {
+		return nil
+	}
+	return fileReportTicket(ctx, r, tracker, debug)
+}
+
+// handleFileTicketQuery answers "file a ticket for this" by filing the most
+// recent report
+func handleFileTicketQuery(ctx context.Context, question string, debug bool) error {
+	tracker, _ := ticket.RequestedTracker(question)
+	if tracker == "" {
+		tracker = viper.GetString("ask.create_ticket")
+	}
+	r, err := latestReport()
+	if err != nil {
+		return err
+	}
+	return fileReportTicket(ctx, r, tracker, debug)
+}
+
+func latestReport() (*reportstore.Report, error) {
+	r, err := reportstore.Latest()
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, fmt.Errorf("no stored reports to file; run a diagnosis or an AWS audit first")
+	}
+	return r, nil
+}
+
+// fileReportTicket files r in tracker, or in whichever of Linear and Notion
+// is configured when tracker is empty, and records the ticket on the report
+func fileReportTicket(ctx context.Context, r *reportstore.Report, tracker string, debug bool) error {
+	if tracker == "" {
+		switch {
+		case linear.ResolveAPIKey() != "":
+			tracker = ticket.TrackerLinear
+		case notion.ResolveToken() != "":
+			tracker = ticket.TrackerNotion
+		default:
+			return fmt.Errorf("no ticket tracker configured (set linear.api_key or notion.integration_token in ~/.clanker.yaml)")
+		}
+	}
+
+	t, err := reportTicket(r)
+	if err != nil {
+		return err
+	}
+
+	var id, url string
+	switch tracker {
+	case ticket.TrackerLinear:
+		client, err := linear.NewClient(linear.ResolveAPIKey(), linear.ResolveWorkspaceID(), linear.ResolveDefaultTeam(), debug)
+		if err != nil {
+			return err
+		}
+		id, url, err = ticket.FileLinear(ctx, client, linear.ResolveDefaultTeam(), t)
+		if err != nil {
+			return err
+		}
+	case ticket.TrackerNotion:
+		client, err := notion.NewClient(notion.ResolveToken(), notion.ResolveDefaultDatabaseID(), debug)
+		if err != nil {
+			return err
+		}
+		id, url, err = ticket.FileNotion(ctx, client, notion.ResolveDefaultDatabaseID(), t)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported ticket tracker %q (use linear or notion)", tracker)
+	}
+
+	if err := reportstore.AddTicket(r, reportstore.Ticket{Tracker: tracker, ID: id, URL: url}); err != nil {
+		fmt.Fprintf(os.Stderr, "[report] warning: could not record ticket on report %s: %v\n", r.ID, err)
+	}
+	fmt.Printf("\nFiled %s %s from report %s: %s\n", tracker, id, r.ID, url)
+	return nil
+}
+
+// reportTicket rebuilds the structured report stored in r and renders it as
+// a ticket
+func reportTicket(r *reportstore.Report) (ticket.Ticket, error) {
+	ref := ticket.Ref{ID: r.ID}
+	if path, err := reportstore.Path(r.ID); err == nil {
+		ref.Path = path
+	}
+	switch r.Kind {
+	case reportstore.KindK8sDiagnosis:
+		var d sre.DiagnosticReport
+		if err := json.Unmarshal(r.Body, &d); err != nil {
+			return ticket.Ticket{}, fmt.Errorf("failed to read diagnosis in report %s: %w", r.ID, err)
+		}
+		return ticket.FromDiagnosticReport(&d, ref), nil
+	case reportstore.KindAWSAudit:
+		var a posture.Report
+		if err := json.Unmarshal(r.Body, &a); err != nil {
+			return ticket.Ticket{}, fmt.Errorf("failed to read audit in report %s: %w", r.ID, err)
+		}
+		return ticket.FromAuditReport(&a, ref), nil
+	}
+	return ticket.Ticket{}, fmt.Errorf("report %s has unsupported kind %q", r.ID, r.Kind)
+}
+
+func printStoredReports(w io.Writer, reports []*reportstore.Report) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tKIND\tTICKETS\tSUMMARY")
+	for _, r := range reports {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n",
+			r.ID, r.CreatedAt.Local().Format("2006-01-02 15:04:05"), r.Kind,
+			len(r.Tickets), truncate(orDash(r.Summary), 60))
+	}
+	tw.Flush()
+}
+
+func printStoredReport(w io.Writer, r *reportstore.Report) {
+	fmt.Fprintf(w, "ID:        %s\n", r.ID)
+	fmt.Fprintf(w, "Kind:      %s\n", r.Kind)
+	fmt.Fprintf(w, "Source:    %s\n", r.Source)
+	if r.Question != "" {
+		fmt.Fprintf(w, "Question:  %s\n", r.Question)
+	}
+	if r.Summary != "" {
+		fmt.Fprintf(w, "Summary:   %s\n", r.Summary)
+	}
+	fmt.Fprintf(w, "Created:   %s\n", r.CreatedAt.Local().Format("2006-01-02 15:04:05 MST"))
+	for _, t := range r.Tickets {
+		fmt.Fprintf(w, "Ticket:    %s %s %s\n", t.Tracker, t.ID, t.URL)
+	}
+	fmt.Fprintf(w, "\n%s\n", r.Text)
+}
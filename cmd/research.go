@@ -392,7 +392,7 @@ emits a final structured JSON payload that clanker-cloud can render into a repor
 		debug := viper.GetBool("debug")
 
 		if strings.TrimSpace(localModelInferenceURL) != "" {
-			viper.Set("ai.providers.openai.local_model_inference_url", strings.TrimSpace(localModelInferenceURL))
+			setFlagOverride("ai.providers.openai.local_model_inference_url", strings.TrimSpace(localModelInferenceURL))
 		}
 		applyCommandAIOverrides(aiProfile, openaiKey, anthropicKey, geminiKey, deepseekKey, cohereKey, minimaxKey, openaiModel, anthropicModel, geminiModel, deepseekModel, cohereModel, minimaxModel, githubModel)
 
@@ -1,20 +1,26 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/bgdnvk/clanker/internal/audit"
 	"github.com/bgdnvk/clanker/internal/aws"
 	"github.com/bgdnvk/clanker/internal/azure"
 	"github.com/bgdnvk/clanker/internal/cloudflare"
 	"github.com/bgdnvk/clanker/internal/digitalocean"
+	"github.com/bgdnvk/clanker/internal/dryrun"
 	"github.com/bgdnvk/clanker/internal/flyio"
 	"github.com/bgdnvk/clanker/internal/gcp"
 	"github.com/bgdnvk/clanker/internal/hetzner"
 	"github.com/bgdnvk/clanker/internal/linear"
+	clankerlog "github.com/bgdnvk/clanker/internal/log"
 	"github.com/bgdnvk/clanker/internal/notion"
 	"github.com/bgdnvk/clanker/internal/oracle"
+	"github.com/bgdnvk/clanker/internal/planrun"
 	"github.com/bgdnvk/clanker/internal/railway"
+	"github.com/bgdnvk/clanker/internal/redact"
 	"github.com/bgdnvk/clanker/internal/sentry"
 	"github.com/bgdnvk/clanker/internal/tencent"
 	"github.com/bgdnvk/clanker/internal/vercel"
@@ -40,11 +46,28 @@ get insights, and perform operations through an intelligent interface.`,
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
-	return rootCmd.Execute()
+	if forwarded, err := forwardToDaemon(os.Args[1:]); forwarded {
+		return err
+	}
+	audit.Begin()
+	cmd, err := rootCmd.ExecuteC()
+	if errors.Is(err, dryrun.ErrStopped) {
+		fmt.Printf("%s %v\n", dryrun.Prefix, err)
+		err = nil
+	}
+	var interrupted *planrun.InterruptedError
+	if errors.As(err, &interrupted) && interrupted.Resume != "" {
+		fmt.Fprintf(os.Stderr, "[apply] %s\n", interrupted.Resume)
+	}
+	if _, auditErr := audit.End(auditCommandLine(cmd), err); auditErr != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write audit log: %v\n", auditErr)
+	}
+	return err
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
+	clankerlog.AddRedactor(redact.String)
 
 	// Add version command
 	rootCmd.AddCommand(&cobra.Command{
@@ -68,6 +91,13 @@ func init() {
 	rootCmd.PersistentFlags().Bool("debug", false, "enable debug output (shows progress + internal diagnostics)")
 	rootCmd.PersistentFlags().Bool("local-mode", true, "enable local mode with rate limiting to prevent system overload (default: true)")
 	rootCmd.PersistentFlags().Int("local-delay", 100, "delay in milliseconds between calls in local mode (default 100ms)")
+	rootCmd.PersistentFlags().Bool("no-redact", false, "send prompts and save conversation history without scrubbing credentials")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "print the commands that would change resources, with secrets masked, instead of running them")
+	rootCmd.PersistentFlags().Duration("step-timeout", 0, "stop any plan step that runs longer than this, e.g. 15m (steps can set their own timeout)")
+	rootCmd.PersistentFlags().Bool("no-cache", false, "run every read-only kubectl call instead of reusing results from the last few seconds")
+	rootCmd.PersistentFlags().Int("chunk-size", 0, "page kubectl list calls in requests of this many items on large clusters (0 uses kubectl's default of 500)")
+	rootCmd.PersistentFlags().String("log", "", "log levels per module, e.g. k8s=debug,cloudflare=warn (a bare level sets the default)")
+	rootCmd.PersistentFlags().String("log-format", "text", "log output format: text or json")
 
 	// Backend integration flags
 	rootCmd.PersistentFlags().String("api-key", "", "Backend API key (or set CLANKER_BACKEND_API_KEY)")
@@ -81,6 +111,13 @@ func init() {
 		{"debug", "debug"},
 		{"local_mode", "local-mode"},
 		{"local_delay_ms", "local-delay"},
+		{"redact.disabled", "no-redact"},
+		{"dry_run", "dry-run"},
+		{"step_timeout", "step-timeout"},
+		{"kubernetes.cache.disabled", "no-cache"},
+		{"kubernetes.chunk_size", "chunk-size"},
+		{"log.level", "log"},
+		{"log.format", "log-format"},
 		{"backend.api_key", "api-key"},
 		{"backend.env", "backend-env"},
 		{"backend.url", "backend-url"},
@@ -96,6 +133,7 @@ func init() {
 
 	// Register AWS static commands
 	awsCmd := aws.CreateAWSCommands()
+	awsCmd.AddCommand(newAWSSSMCmd())
 	rootCmd.AddCommand(awsCmd)
 
 	// Register GCP static commands
@@ -205,4 +243,18 @@ func initConfig() {
 			fmt.Println("Using config file:", viper.ConfigFileUsed())
 		}
 	}
+
+	// A dry run that stops at a skipped command returns dryrun.ErrStopped,
+	// which Execute reports itself rather than as a failure
+	rootCmd.SilenceErrors = dryrun.Enabled()
+	rootCmd.SilenceUsage = dryrun.Enabled()
+
+	if err := clankerlog.Configure(clankerlog.Options{
+		Spec:   viper.GetString("log.level"),
+		Format: viper.GetString("log.format"),
+		Debug:  viper.GetBool("debug"),
+		Output: os.Stderr,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: --log: %v\n", err)
+	}
 }
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bgdnvk/clanker/internal/routing"
+	"github.com/spf13/cobra"
+)
+
+var routeCmd = &cobra.Command{
+	Use:   "route",
+	Short: "Correct where clanker ask routes questions",
+	Long: `Teach the router where questions belong.
+
+A correction sends that exact question (ignoring case, spacing and trailing
+punctuation) to the corrected agent from then on, and is shown to the LLM
+classifier as an example when it routes similar questions. Corrections are
+stored in ~/.clanker/routing/corrections.json.
+
+Examples:
+  clanker route correct --last k8s
+  clanker route correct --question "what is eating memory on web-1" observability
+  clanker route list
+  clanker route forget "what is eating memory on web-1"`,
+}
+
+var routeCorrectCmd = &cobra.Command{
+	Use:   "correct <agent>",
+	Short: "Record the agent a question should have gone to",
+	Args:  cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return pinnableAgentNames(), cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		useLast, _ := cmd.Flags().GetBool("last")
+		question, _ := cmd.Flags().GetString("question")
+		if useLast == (question != "") {
+			return fmt.Errorf("specify exactly one of --last or --question")
+		}
+
+		agent, ok := resolveAgentName(args[0])
+		if !ok {
+			return fmt.Errorf("unknown agent %q (available: %s)", args[0], strings.Join(pinnableAgentNames(), ", "))
+		}
+
+		was := ""
+		if useLast {
+			last, err := routing.LoadLast()
+			if err != nil {
+				return err
+			}
+			if last == nil {
+				return fmt.Errorf("no routed question recorded yet; use --question instead")
+			}
+			question, was = last.Question, last.Agent
+		}
+
+		correction, err := routing.AddCorrection(question, agent, was)
+		if err != nil {
+			return err
+		}
+		if correction.Was != "" {
+			fmt.Printf("Routed to %s before; %q now goes to %s.\n", correction.Was, correction.Question, correction.Agent)
+		} else {
+			fmt.Printf("%q now goes to %s.\n", correction.Question, correction.Agent)
+		}
+		return nil
+	},
+}
+
+var routeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List routing corrections, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		corrections, err := routing.LoadCorrections()
+		if err != nil {
+			return err
+		}
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(corrections)
+		}
+		if len(corrections) == 0 {
+			fmt.Println("No routing corrections.")
+			return nil
+		}
+		printRouteCorrections(os.Stdout, corrections)
+		return nil
+	},
+}
+
+var routeForgetCmd = &cobra.Command{
+	Use:   "forget <question>",
+	Short: "Remove the routing correction for a question",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		removed, err := routing.RemoveCorrection(args[0])
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("no correction for %q (see: clanker route list)", args[0])
+		}
+		fmt.Printf("Removed correction for %q\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(routeCmd)
+	routeCmd.AddCommand(routeCorrectCmd)
+	routeCmd.AddCommand(routeListCmd)
+	routeCmd.AddCommand(routeForgetCmd)
+
+	routeCorrectCmd.Flags().Bool("last", false, "Correct the question clanker ask routed most recently")
+	routeCorrectCmd.Flags().String("question", "", "Correct this question instead of the last one")
+	routeListCmd.Flags().Bool("json", false, "Output corrections as JSON")
+}
+
+// pinnableAgentNames lists the agents questions can be pinned or corrected to
+func pinnableAgentNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, agent := range pinnableAgents {
+		if !seen[agent] {
+			seen[agent] = true
+			names = append(names, agent)
+		}
+	}
+	for _, c := range pluginConfigs() {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func printRouteCorrections(w io.Writer, corrections []routing.Correction) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGENT\tWAS\tCORRECTED\tQUESTION")
+	for i := len(corrections) - 1; i >= 0; i-- {
+		c := corrections[i]
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Agent, orDash(c.Was), c.CreatedAt.Local().Format("2006-01-02 15:04"), truncate(c.Question, 80))
+	}
+	tw.Flush()
+}
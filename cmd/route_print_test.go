@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/routing"
+)
+
+func TestPrintRouteCorrections(t *testing.T) {
+	var buf bytes.Buffer
+	printRouteCorrections(&buf, []routing.Correction{
+		{Question: "largest tables", Agent: "agent-database", Was: "cli", CreatedAt: time.Now()},
+		{Question: "pods in shop", Agent: "k8s", CreatedAt: time.Now()},
+	})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "AGENT") {
+		t.Fatalf("output:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "k8s") || !strings.Contains(lines[1], " - ") {
+		t.Errorf("newest correction should come first with no previous agent:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[2], "cli") {
+		t.Errorf("previous agent missing:\n%s", buf.String())
+	}
+}
+
+func TestRouteCorrectRejectsUnknownAgent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	routeCorrectCmd.Flags().Set("question", "pods in shop")
+	t.Cleanup(func() { routeCorrectCmd.Flags().Set("question", "") })
+	if err := routeCorrectCmd.RunE(routeCorrectCmd, []string{"bogus"}); err == nil || !strings.Contains(err.Error(), "unknown agent") {
+		t.Errorf("err = %v", err)
+	}
+	if err := routeCorrectCmd.RunE(routeCorrectCmd, []string{"kubernetes"}); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := routing.CorrectionFor("pods in shop"); !ok || c.Agent != "k8s" {
+		t.Errorf("alias should be stored as its agent, got %+v", c)
+	}
+}
@@ -1,6 +1,12 @@
 package cmd
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/routing"
+	"github.com/spf13/viper"
+)
 
 // Routing regression suite — every case here failed pre-fix on master and
 // is locked in here to prevent recurrence. The CLI's
@@ -108,6 +114,398 @@ func TestShouldRouteToObservabilityAgent_DoesNotStealCICDOrDB(t *testing.T) {
 	}
 }
 
+func TestShouldRouteToAWSCostAgent(t *testing.T) {
+	t.Cleanup(func() { viper.Set("infra.default_provider", nil) })
+
+	for _, q := range []string{
+		"what did we spend on EC2 last month",
+		"top 5 services by cost this week",
+	} {
+		if !shouldRouteToAWSCostAgent(q) {
+			t.Errorf("query %q SHOULD route to the AWS cost agent", q)
+		}
+	}
+	for _, q := range []string{
+		"how can we reduce our ec2 costs",
+		"what did we spend on gcp last month",
+		"list running ec2 instances",
+	} {
+		if shouldRouteToAWSCostAgent(q) {
+			t.Errorf("query %q should NOT route to the AWS cost agent", q)
+		}
+	}
+
+	viper.Set("infra.default_provider", "gcp")
+	if shouldRouteToAWSCostAgent("what did we spend last month") {
+		t.Error("spend question without AWS keywords should follow a non-AWS default provider")
+	}
+	if !shouldRouteToAWSCostAgent("what did we spend on aws last month") {
+		t.Error("explicit AWS spend question should route to the AWS cost agent")
+	}
+}
+
+func TestShouldRouteToAWSAuditAgent(t *testing.T) {
+	t.Cleanup(func() { viper.Set("infra.default_provider", nil) })
+
+	for _, q := range []string{
+		"audit my AWS security",
+		"what is the security posture of our aws account",
+	} {
+		if !shouldRouteToAWSAuditAgent(q) {
+			t.Errorf("query %q SHOULD route to the AWS audit agent", q)
+		}
+	}
+	for _, q := range []string{
+		"audit the iam role trust policy",
+		"list security groups",
+		"run a security audit of the gcp project",
+	} {
+		if shouldRouteToAWSAuditAgent(q) {
+			t.Errorf("query %q should NOT route to the AWS audit agent", q)
+		}
+	}
+
+	viper.Set("infra.default_provider", "gcp")
+	if shouldRouteToAWSAuditAgent("run a security audit") {
+		t.Error("audit question without AWS keywords should follow a non-AWS default provider")
+	}
+}
+
+func TestShouldRouteToAWSLogsAgent(t *testing.T) {
+	t.Cleanup(func() { viper.Set("infra.default_provider", nil) })
+
+	for _, q := range []string{
+		"show errors in lambda checkout from the last hour",
+		"logs for /ecs/orders in the past 30 minutes",
+	} {
+		if !shouldRouteToAWSLogsAgent(q) {
+			t.Errorf("query %q SHOULD route to the AWS logs agent", q)
+		}
+	}
+	for _, q := range []string{
+		"show me recent error logs for prod api",
+		"errors in pod checkout in kubernetes",
+		"create a lambda named checkout that writes logs",
+	} {
+		if shouldRouteToAWSLogsAgent(q) {
+			t.Errorf("query %q should NOT route to the AWS logs agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToAWSRDSAgent(t *testing.T) {
+	for _, q := range []string{
+		"list rds instances with storage and connection metrics",
+		"take a snapshot of rds instance orders-db",
+		"restore aurora cluster analytics to 2 hours ago",
+		"resize rds instance orders-db to db.r6g.large",
+		"any pending rds maintenance?",
+	} {
+		if !shouldRouteToAWSRDSAgent(q) {
+			t.Errorf("query %q SHOULD route to the AWS RDS agent", q)
+		}
+	}
+	for _, q := range []string{
+		"list databases in production",
+		"how much did rds cost last month",
+		"show rds error logs",
+		"create an rds postgres instance",
+		"show cloud sql instances in gcp",
+	} {
+		if shouldRouteToAWSRDSAgent(q) {
+			t.Errorf("query %q should NOT route to the AWS RDS agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToSecretsAgent(t *testing.T) {
+	for _, q := range []string{
+		"list all secrets",
+		"which deployments use secret db-credentials",
+		"rotate the /app/db-password parameter in parameter store",
+		"create a secret in gcp named api-key",
+	} {
+		if !shouldRouteToSecretsAgent(q) {
+			t.Errorf("query %q SHOULD route to the secrets agent", q)
+		}
+	}
+	for _, q := range []string{
+		"list github actions secrets",
+		"rotate the access key of user ci-deploy",
+		"list secrets in azure key vault",
+		"list digitalocean secrets",
+	} {
+		if shouldRouteToSecretsAgent(q) {
+			t.Errorf("query %q should NOT route to the secrets agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToCertsAgent(t *testing.T) {
+	for _, q := range []string{
+		"which certs expire soon",
+		"list acm certificates",
+		"when does the tls certificate for shop.example.com expire",
+	} {
+		if !shouldRouteToCertsAgent(q) {
+			t.Errorf("query %q SHOULD route to the certificate agent", q)
+		}
+	}
+	for _, q := range []string{
+		"check kubeadm certs expiration",
+		"which load balancers use tls 1.0",
+		"list azure app service certificates",
+		"which vercel certificates expire soon",
+	} {
+		if shouldRouteToCertsAgent(q) {
+			t.Errorf("query %q should NOT route to the certificate agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToDNSTruthAgent(t *testing.T) {
+	for _, q := range []string{
+		"where does api.example.com actually resolve and who manages it",
+		"is shop.example.com delegated to route53 or cloudflare",
+	} {
+		if !shouldRouteToDNSTruthAgent(q) {
+			t.Errorf("query %q SHOULD route to the DNS truth agent", q)
+		}
+	}
+	for _, q := range []string{
+		"add a CNAME www pointing to example.pages.dev",
+		"export dns records for example.com",
+		"list route53 hosted zones",
+	} {
+		if shouldRouteToDNSTruthAgent(q) {
+			t.Errorf("query %q should NOT route to the DNS truth agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToFlyioAgent(t *testing.T) {
+	for _, q := range []string{
+		"scale the api fly app to 3 machines in ams",
+		"show fly machines",
+		"remove the OLD_KEY fly secrets from app api",
+	} {
+		if !shouldRouteToFlyioAgent(q) {
+			t.Errorf("query %q SHOULD route to the Fly.io agent", q)
+		}
+	}
+	for _, q := range []string{
+		"why is my fly app slow",
+		"scale the api deployment to 3 replicas",
+		"how do I write a fly.toml",
+	} {
+		if shouldRouteToFlyioAgent(q) {
+			t.Errorf("query %q should NOT route to the Fly.io agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToVercelAndRailwayAgents(t *testing.T) {
+	for _, q := range []string{
+		"list my vercel projects",
+		"show vercel build logs for project my-site",
+		"remove vercel env var OLD_TOKEN from project my-site",
+	} {
+		if !shouldRouteToVercelAgent(q) {
+			t.Errorf("query %q SHOULD route to the Vercel agent", q)
+		}
+	}
+	for _, q := range []string{
+		"list railway deployments",
+		"redeploy the api service on railway",
+	} {
+		if !shouldRouteToRailwayAgent(q) {
+			t.Errorf("query %q SHOULD route to the Railway agent", q)
+		}
+	}
+	for _, q := range []string{
+		"why did my last vercel deploy fail",
+		"how do I redeploy on railway",
+		"list my fly apps",
+	} {
+		if shouldRouteToVercelAgent(q) || shouldRouteToRailwayAgent(q) {
+			t.Errorf("query %q should NOT route to the Vercel or Railway ops path", q)
+		}
+	}
+}
+
+func TestShouldRouteToSentryAgent(t *testing.T) {
+	for _, q := range []string{
+		"what are the top crashers this week",
+		"release health of project backend",
+		"assign BACKEND-42 to jane@example.com in sentry",
+	} {
+		if !shouldRouteToSentryAgent(q) {
+			t.Errorf("query %q SHOULD route to the Sentry agent", q)
+		}
+	}
+	for _, q := range []string{
+		"how do I resolve sentry issues from the cli",
+		"which lambda functions have errors",
+		"show cloudwatch alarms",
+	} {
+		if shouldRouteToSentryAgent(q) {
+			t.Errorf("query %q should NOT route to the Sentry agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToTicketAgent(t *testing.T) {
+	for _, q := range []string{
+		"file a ticket for this",
+		"open a linear ticket for the diagnosis",
+		"create a notion page for that audit report",
+	} {
+		if !shouldRouteToTicketAgent(q) {
+			t.Errorf("query %q SHOULD route to the ticket agent", q)
+		}
+	}
+	for _, q := range []string{
+		"how do I file a ticket in linear",
+		"create an issue in linear for the login bug",
+		"list my linear issues",
+	} {
+		if shouldRouteToTicketAgent(q) {
+			t.Errorf("query %q should NOT route to the ticket agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToAWSLambdaAgent(t *testing.T) {
+	for _, q := range []string{
+		"list lambda functions with their error rates",
+		"set env LOG_LEVEL=debug on lambda checkout",
+		"deploy ./dist/checkout.zip to lambda checkout",
+		"publish a new version of lambda checkout",
+	} {
+		if !shouldRouteToAWSLambdaAgent(q) {
+			t.Errorf("query %q SHOULD route to the AWS Lambda agent", q)
+		}
+	}
+	for _, q := range []string{
+		"show errors in lambda checkout from the last hour",
+		"create a lambda",
+		"how much do my lambdas cost",
+		"how many lambdas do i have",
+	} {
+		if shouldRouteToAWSLambdaAgent(q) {
+			t.Errorf("query %q should NOT route to the AWS Lambda agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToAzureInfraAgent(t *testing.T) {
+	for _, q := range []string{
+		"list my azure vms",
+		"which azure storage accounts allow public blob access",
+		"create an azure storage account named appdata in resource group app-rg",
+		"deallocate azure vm batch-01",
+		"show vnets and their subnets",
+	} {
+		if !shouldRouteToAzureInfraAgent(q) {
+			t.Errorf("query %q SHOULD route to the Azure infrastructure agent", q)
+		}
+	}
+	for _, q := range []string{
+		"list my ec2 instances",
+		"how much do my azure vms cost",
+		"show azure aks clusters",
+		"compare azure vms with gcp compute engine instances",
+	} {
+		if shouldRouteToAzureInfraAgent(q) {
+			t.Errorf("query %q should NOT route to the Azure infrastructure agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToGitHubActionsAgent(t *testing.T) {
+	for _, q := range []string{
+		"trigger the deploy workflow on main with environment=staging",
+		"re-run the failed jobs of run 9876543210",
+		"cancel the running ci workflow on feature/login",
+		"approve the pending deployment to production for run 9876543210",
+	} {
+		if !shouldRouteToGitHubActionsAgent(q) {
+			t.Errorf("query %q SHOULD route to the GitHub Actions agent", q)
+		}
+	}
+	for _, q := range []string{
+		"show me failing github actions workflows",
+		"setup github actions for deployment",
+		"how do I re-run a workflow",
+		"run `uptime` on all instances tagged role=web",
+	} {
+		if shouldRouteToGitHubActionsAgent(q) {
+			t.Errorf("query %q should NOT route to the GitHub Actions agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToPRReviewAgent(t *testing.T) {
+	for _, q := range []string{
+		"summarize PR #123 and flag risky changes",
+		"review pull request 42 in acme/shop",
+		"what are the risks in https://github.com/acme/shop/pull/7",
+	} {
+		if !shouldRouteToPRReviewAgent(q) {
+			t.Errorf("query %q SHOULD route to the PR review agent", q)
+		}
+	}
+	for _, q := range []string{
+		"list open pull requests",
+		"review my IAM policies",
+		"show me failing github actions workflows",
+	} {
+		if shouldRouteToPRReviewAgent(q) {
+			t.Errorf("query %q should NOT route to the PR review agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToGitLabCIAgent(t *testing.T) {
+	for _, q := range []string{
+		"retry the failed gitlab pipeline on main",
+		"rerun https://gitlab.com/acme/shop/-/jobs/77",
+	} {
+		if !shouldRouteToGitLabCIAgent(q) {
+			t.Errorf("query %q SHOULD route to the GitLab CI agent", q)
+		}
+	}
+	for _, q := range []string{
+		"re-run the failed jobs of run 9876543210",
+		"why did the gitlab pipeline fail",
+	} {
+		if shouldRouteToGitLabCIAgent(q) {
+			t.Errorf("query %q should NOT route to the GitLab CI agent", q)
+		}
+	}
+}
+
+func TestShouldRouteToTerraformStateAgent(t *testing.T) {
+	for _, q := range []string{
+		"what's in the terraform state for workspace prod",
+		"is there drift",
+		"which resources are managed outside terraform",
+	} {
+		if !shouldRouteToTerraformStateAgent(q) {
+			t.Errorf("query %q SHOULD route to the Terraform state agent", q)
+		}
+	}
+	for _, q := range []string{
+		"is argocd showing drift for the payments app",
+		"terraform plan for staging",
+		"what state is my ec2 instance in",
+	} {
+		if shouldRouteToTerraformStateAgent(q) {
+			t.Errorf("query %q should NOT route to the Terraform state agent", q)
+		}
+	}
+}
+
 // End-to-end via determineRoutingDecisionDetailsWithContext (the path
 // `clanker ask --route-only` exposes to clanker-cloud's backend).
 // Each case below was wrong on master pre-fix.
@@ -137,6 +535,78 @@ func TestDetermineRoutingDecision_PostFixRegressions(t *testing.T) {
 		{"trace 500 errors in cloud run", "agent-observability", "trace/error request"},
 		{"show cloudwatch alarms and warning logs", "agent-observability", "cloudwatch alarms/logs"},
 
+		// Security posture audit vs IAM analysis
+		{"audit my AWS security", "aws-audit", "account-wide posture audit"},
+		{"analyze iam role trust policy", "iam", "single-principal IAM question"},
+		{"attach ReadOnlyAccess policy to role auditor", "iam", "policy attachment is an IAM change"},
+		{"rotate the access key of user ci-deploy", "iam", "access key rotation"},
+		{"show access analyzer findings and external exposure", "iam", "access analyzer report"},
+
+		// Logs for a named source run as a Logs Insights query
+		{"show errors in lambda checkout from the last hour", "aws-logs", "named Lambda function"},
+
+		// Instance access goes through SSM
+		{"connect to instance i-0abc12345678def00", "aws-ssm", "instance ID with connect intent"},
+		{"run `uptime` on all instances tagged role=web", "aws-ssm", "command with a tag selector"},
+
+		// RDS operations name RDS or Aurora explicitly
+		{"list rds instances with storage and connection metrics", "aws-rds", "rds listing with metrics"},
+		{"take a snapshot of rds instance orders-db", "aws-rds", "rds snapshot"},
+		{"restore aurora cluster analytics to 2 hours ago", "aws-rds", "aurora cluster is not a k8s cluster"},
+
+		// Lambda operations; log questions for a function stay with aws-logs
+		{"list lambda functions with their error rates", "aws-lambda", "lambda listing"},
+		{"set env LOG_LEVEL=debug on lambda checkout", "aws-lambda", "lambda environment change"},
+
+		// Azure core services outside AKS
+		{"list my azure vms", "azure-infra", "azure vm listing"},
+		{"create an azure container registry named clankerimages", "azure-infra", "azure registry create, not the aws maker"},
+
+		// Secrets across Secrets Manager, Parameter Store, GCP and Kubernetes
+		{"which deployments use secret db-credentials", "secrets", "secret reference check, not a k8s query"},
+		{"rotate secret prod/db in secrets manager", "secrets", "secret rotation, not the aws maker"},
+		{"list kubernetes secrets in namespace shop", "secrets", "k8s secret listing by metadata"},
+
+		// Certificate expiry across ACM, Cloudflare, GCP and cert-manager
+		{"which certs expire soon", "certs", "cross-provider certificate expiry"},
+		{"which cloudflare certificates expire in the next 14 days", "certs", "certificate expiry, not the cloudflare agent"},
+		{"show cert-manager certificates", "certs", "certificate inventory, not a k8s query"},
+
+		// Live DNS against Route53 and Cloudflare
+		{"where does api.example.com actually resolve and who manages it", "dns-truth", "dns truth, not a cloudflare dns query"},
+		{"find dangling records in example.com", "dns-truth", "dangling record scan"},
+
+		// Fly.io operations stay with the flyio agent
+		{"scale the api fly app to 3 machines in ams", "flyio", "fly scale, not the aws maker"},
+		{"set the DATABASE_URL fly secrets on app api", "flyio", "fly secrets, not the secrets agent"},
+		{"deploy the web fly app from Dockerfile.prod", "flyio", "fly deploy, not a container deploy"},
+
+		// Vercel and Railway operations stay with their agents
+		{"redeploy vercel project my-site", "vercel", "vercel redeploy, not a deploy maker"},
+		{"set the DATABASE_URL vercel env var on project my-site", "vercel", "vercel env, not the secrets agent"},
+		{"show the build logs of the last failed railway deploy of service api", "railway", "railway build logs, not a log query"},
+		{"set the STRIPE_KEY railway variable on service web", "railway", "railway variables, not the secrets agent"},
+
+		// Sentry triage goes to the Sentry agent, not the observability fan-out
+		{"what are the top crashers this week", "sentry", "sentry top issues"},
+		{"show sentry issues in release 2.4.1 on production", "sentry", "sentry issue search by release"},
+		{"resolve sentry issue BACKEND-42", "sentry", "sentry resolve, not dns resolution"},
+
+		// Filing a ticket refers to the last stored report
+		{"file a linear ticket for this audit", "ticket", "ticket filing, not another audit"},
+
+		// Changes to GitHub Actions runs are plans, not CI/CD questions
+		{"re-run failed jobs of the latest ci.yml run on main", "github-actions", "workflow re-run"},
+		{"approve the pending deployment to production for run 9876543210", "github-actions", "deployment approval, not terraform apply"},
+		{"summarize PR #123 and flag risky changes", "pr-review", "pull request review"},
+		{"retry the failed gitlab pipeline on main", "gitlab-ci", "gitlab pipeline retry"},
+
+		// State and drift questions go to the state agent, not the general terraform context
+		{"what's in the terraform state for workspace prod", "terraform-state", "state inventory"},
+		{"is there drift", "terraform-state", "drift check"},
+		{"what would pulumi preview change on the prod stack", "pulumi", "pulumi preview, not a terraform plan"},
+		{"list the pulumi stack outputs", "pulumi", "pulumi outputs"},
+
 		// Real cases that must still route correctly
 		{"how many lambda do i have", "cli", "no action keyword + non-DB"},
 		{"show me my postgres tables", "agent-database", "engine + table combo"},
@@ -167,3 +637,59 @@ managed database inventory present`
 		t.Fatalf("lambda inventory question with appended context should route to cli, got %q reason=%q", decision.Agent, decision.Reason)
 	}
 }
+
+func TestDetermineRoutingDecision_RanksCandidates(t *testing.T) {
+	decision := determineRoutingDecisionDetailsWithContext("create a k8s deployment", "")
+	if decision.Agent != "k8s-maker" || decision.Confidence <= 0 || decision.Confidence >= 1 {
+		t.Fatalf("decision = %+v", decision)
+	}
+	if len(decision.Candidates) < 2 || decision.Candidates[0].Agent != "k8s-maker" {
+		t.Errorf("candidates = %+v", decision.Candidates)
+	}
+	if len(decision.Candidates[0].Signals) == 0 {
+		t.Error("top candidate should explain which signals matched")
+	}
+}
+
+func TestDetermineRoutingDecision_FanOutAndServices(t *testing.T) {
+	decision := determineRoutingDecisionDetailsWithContext("check iam roles and the pods in prod", "")
+	if decision.Agent != "iam" || strings.Join(decision.FanOut, ",") != "iam,k8s" {
+		t.Errorf("decision = %+v", decision)
+	}
+
+	decision = determineRoutingDecisionDetailsWithContext("check both aws and github", "")
+	if decision.Agent != "cli" || !strings.Contains(strings.Join(decision.Services, ","), "aws") || !strings.Contains(strings.Join(decision.Services, ","), "github") {
+		t.Errorf("decision = %+v", decision)
+	}
+}
+
+func TestDetermineRoutingDecision_HonorsRoutingForce(t *testing.T) {
+	previous := viper.GetString("routing.force")
+	t.Cleanup(func() { viper.Set("routing.force", previous) })
+
+	viper.Set("routing.force", "database")
+	decision := determineRoutingDecisionDetailsWithContext("how many lambdas do i have", "")
+	if decision.Agent != "agent-database" || !decision.Pinned || decision.Confidence != 1 {
+		t.Errorf("pinned decision = %+v", decision)
+	}
+
+	viper.Set("routing.force", "nonsense")
+	decision = determineRoutingDecisionDetailsWithContext("how many lambdas do i have", "")
+	if decision.Agent != "cli" || decision.Pinned {
+		t.Errorf("unknown pin should be ignored, got %+v", decision)
+	}
+}
+
+func TestDetermineRoutingDecision_AppliesCorrections(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := routing.AddCorrection("How many lambdas do I have?", "k8s", "cli"); err != nil {
+		t.Fatal(err)
+	}
+	decision := determineRoutingDecisionDetailsWithContext("how many lambdas do i have", "")
+	if decision.Agent != "k8s" || !decision.Pinned || !strings.Contains(decision.Reason, "route correct") {
+		t.Errorf("corrected decision = %+v", decision)
+	}
+	if decision := determineRoutingDecisionDetailsWithContext("how many buckets do i have", ""); decision.Agent != "cli" {
+		t.Errorf("other questions should route normally, got %+v", decision)
+	}
+}
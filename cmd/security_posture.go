@@ -0,0 +1,413 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	securityPostureStateEncrypted   = "encrypted"
+	securityPostureStateUnencrypted = "unencrypted"
+	securityPostureStateEnforced    = "enforced"
+	securityPostureStateOptional    = "optional"
+	securityPostureStatePublic      = "public"
+	securityPostureStatePrivate     = "private"
+	securityPostureStateUnknown     = "unknown"
+
+	securityPostureKeyManaged = "managed"
+	securityPostureKeyCMK     = "cmk"
+	securityPostureKeyNone    = "none"
+)
+
+var (
+	securityPostureOutput    string
+	securityPostureFramework string
+)
+
+// securityPostureRow is one data store in the encryption/compliance matrix.
+type securityPostureRow struct {
+	ResourceID     string   `json:"resourceId"`
+	Name           string   `json:"name"`
+	StoreType      string   `json:"storeType"`
+	Provider       string   `json:"provider"`
+	Region         string   `json:"region,omitempty"`
+	AtRest         string   `json:"atRest"`
+	InTransit      string   `json:"inTransit"`
+	KeyType        string   `json:"keyType"`
+	PublicExposure string   `json:"publicExposure"`
+	Gaps           []string `json:"gaps,omitempty"`
+	Controls       []string `json:"controls,omitempty"`
+}
+
+type securityPostureSummary struct {
+	DataStores    int `json:"dataStores"`
+	Unencrypted   int `json:"unencrypted"`
+	TransitGaps   int `json:"transitGaps"`
+	ProviderKeys  int `json:"providerManagedKeys"`
+	CustomerKeys  int `json:"customerManagedKeys"`
+	PublicStores  int `json:"publicStores"`
+	UnknownFields int `json:"unknownFields"`
+}
+
+type securityPostureMatrix struct {
+	GeneratedAt string                 `json:"generatedAt,omitempty"`
+	Framework   string                 `json:"framework,omitempty"`
+	Summary     securityPostureSummary `json:"summary"`
+	Rows        []securityPostureRow   `json:"rows"`
+	Warnings    []string               `json:"warnings,omitempty"`
+}
+
+// securityPostureControls maps each posture gap to the compliance controls it
+// violates, keyed by framework so --framework can narrow the output.
+var securityPostureControls = map[string]map[string][]string{
+	"at-rest": {
+		"soc2":     {"SOC 2 CC6.1"},
+		"pci-dss":  {"PCI DSS 3.5"},
+		"hipaa":    {"HIPAA 164.312(a)(2)(iv)"},
+		"iso27001": {"ISO 27001 A.8.24"},
+	},
+	"in-transit": {
+		"soc2":     {"SOC 2 CC6.7"},
+		"pci-dss":  {"PCI DSS 4.2"},
+		"hipaa":    {"HIPAA 164.312(e)(1)"},
+		"iso27001": {"ISO 27001 A.8.24"},
+	},
+	"key-management": {
+		"soc2":     {"SOC 2 CC6.1"},
+		"pci-dss":  {"PCI DSS 3.6"},
+		"iso27001": {"ISO 27001 A.8.24"},
+	},
+	"public-exposure": {
+		"soc2":     {"SOC 2 CC6.6"},
+		"pci-dss":  {"PCI DSS 1.3"},
+		"hipaa":    {"HIPAA 164.312(a)(1)"},
+		"iso27001": {"ISO 27001 A.8.3"},
+	},
+}
+
+var securityPostureCmd = &cobra.Command{
+	Use:   "posture",
+	Short: "Show an encryption and exposure matrix for every data store",
+	Long: `Build a cross-provider encryption/compliance posture matrix from the
+current estate snapshot.
+
+For each data store (S3, EBS, RDS, GCS, Kubernetes PVCs, Cloudflare R2) the
+matrix shows encryption at rest and in transit, whether keys are provider
+managed or customer managed (CMK), and public exposure. Gaps are mapped to
+SOC 2, PCI DSS, HIPAA, and ISO 27001 controls.
+
+Examples:
+  clanker security posture
+  clanker security posture --framework pci-dss
+  clanker security posture -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		framework := strings.ToLower(strings.TrimSpace(securityPostureFramework))
+		if framework != "" && !securityPostureFrameworkKnown(framework) {
+			return fmt.Errorf("unknown framework %q (supported: %s)", framework, strings.Join(securityPostureFrameworks(), ", "))
+		}
+
+		estate, warnings := loadDeepResearchEstateSnapshot()
+		matrix := buildSecurityPostureMatrix(estate.Resources, framework)
+		matrix.GeneratedAt = estate.LastUpdated
+		matrix.Warnings = uniqueNonEmptyStrings(warnings)
+
+		switch strings.ToLower(securityPostureOutput) {
+		case "json":
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(matrix)
+		default:
+			printSecurityPostureMatrix(os.Stdout, matrix)
+			return nil
+		}
+	},
+}
+
+func init() {
+	securityCmd.AddCommand(securityPostureCmd)
+
+	securityPostureCmd.Flags().StringVarP(&securityPostureOutput, "output", "o", "table", "Output format (table, json)")
+	securityPostureCmd.Flags().StringVar(&securityPostureFramework, "framework", "", "Only report controls for one framework (soc2, pci-dss, hipaa, iso27001)")
+}
+
+func securityPostureFrameworks() []string {
+	seen := map[string]struct{}{}
+	for _, byFramework := range securityPostureControls {
+		for framework := range byFramework {
+			seen[framework] = struct{}{}
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for framework := range seen {
+		out = append(out, framework)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func securityPostureFrameworkKnown(framework string) bool {
+	for _, known := range securityPostureFrameworks() {
+		if known == framework {
+			return true
+		}
+	}
+	return false
+}
+
+func buildSecurityPostureMatrix(resources []deepResearchResource, framework string) securityPostureMatrix {
+	matrix := securityPostureMatrix{Framework: framework, Rows: []securityPostureRow{}}
+	for _, resource := range resources {
+		storeType := classifySecurityPostureStore(resource)
+		if storeType == "" {
+			continue
+		}
+		row := buildSecurityPostureRow(resource, storeType, framework)
+		matrix.Rows = append(matrix.Rows, row)
+
+		matrix.Summary.DataStores++
+		if row.AtRest == securityPostureStateUnencrypted {
+			matrix.Summary.Unencrypted++
+		}
+		if row.InTransit == securityPostureStateOptional {
+			matrix.Summary.TransitGaps++
+		}
+		switch row.KeyType {
+		case securityPostureKeyManaged:
+			matrix.Summary.ProviderKeys++
+		case securityPostureKeyCMK:
+			matrix.Summary.CustomerKeys++
+		}
+		if row.PublicExposure == securityPostureStatePublic {
+			matrix.Summary.PublicStores++
+		}
+		for _, value := range []string{row.AtRest, row.InTransit, row.KeyType, row.PublicExposure} {
+			if value == securityPostureStateUnknown {
+				matrix.Summary.UnknownFields++
+			}
+		}
+	}
+
+	sort.SliceStable(matrix.Rows, func(i, j int) bool {
+		if len(matrix.Rows[i].Gaps) != len(matrix.Rows[j].Gaps) {
+			return len(matrix.Rows[i].Gaps) > len(matrix.Rows[j].Gaps)
+		}
+		if matrix.Rows[i].StoreType != matrix.Rows[j].StoreType {
+			return matrix.Rows[i].StoreType < matrix.Rows[j].StoreType
+		}
+		return matrix.Rows[i].Name < matrix.Rows[j].Name
+	})
+	return matrix
+}
+
+// classifySecurityPostureStore returns the data store kind for a resource, or
+// "" when the resource does not hold data at rest.
+func classifySecurityPostureStore(resource deepResearchResource) string {
+	typeLower := strings.ToLower(strings.TrimSpace(resource.Type))
+	switch {
+	case typeLower == "r2" || deepResearchContainsAny(typeLower, "r2_bucket", "r2bucket", "cloudflare_r2"):
+		return "r2"
+	case deepResearchContainsAny(typeLower, "s3"):
+		return "s3"
+	case deepResearchContainsAny(typeLower, "ebs", "aws_volume", "ec2_volume"):
+		return "ebs"
+	case deepResearchContainsAny(typeLower, "rds", "aurora"):
+		return "rds"
+	case deepResearchContainsAny(typeLower, "gcs", "storage_bucket", "storagebucket", "cloud_storage"):
+		return "gcs"
+	case deepResearchContainsAny(typeLower, "pvc", "persistentvolumeclaim", "persistent_volume_claim"):
+		return "pvc"
+	default:
+		return ""
+	}
+}
+
+func buildSecurityPostureRow(resource deepResearchResource, storeType string, framework string) securityPostureRow {
+	attrs := resource.Attributes
+	row := securityPostureRow{
+		ResourceID: resource.ID,
+		Name:       deepResearchResourceLabel(resource),
+		StoreType:  storeType,
+		Provider:   inferDeepResearchProvider(resource),
+		Region:     resource.Region,
+	}
+
+	kmsKey := deepResearchFirstNonEmptyAttr(attrs, "kmsKeyId", "kmsKeyArn", "kmsKeyName", "kmsMasterKeyId", "defaultKmsKeyName", "encryptionKey", "customerManagedKey")
+	row.AtRest = securityPostureAtRest(storeType, attrs, kmsKey)
+	row.KeyType = securityPostureKeyType(row.AtRest, attrs, kmsKey)
+	row.InTransit = securityPostureInTransit(storeType, attrs)
+	row.PublicExposure = securityPosturePublicExposure(storeType, resource)
+
+	gaps := []string{}
+	if row.AtRest == securityPostureStateUnencrypted {
+		gaps = append(gaps, "at-rest")
+	}
+	if row.InTransit == securityPostureStateOptional {
+		gaps = append(gaps, "in-transit")
+	}
+	if row.KeyType == securityPostureKeyManaged && securityPostureSensitive(resource) {
+		gaps = append(gaps, "key-management")
+	}
+	if row.PublicExposure == securityPostureStatePublic {
+		gaps = append(gaps, "public-exposure")
+	}
+	row.Gaps = gaps
+	row.Controls = securityPostureControlsFor(gaps, framework)
+	return row
+}
+
+func securityPostureAtRest(storeType string, attrs map[string]interface{}, kmsKey string) string {
+	for _, key := range []string{"storageEncrypted", "encrypted", "encryptionEnabled", "bucketEncryption"} {
+		if value, ok := deepResearchBoolAttr(attrs, key); ok {
+			if value {
+				return securityPostureStateEncrypted
+			}
+			return securityPostureStateUnencrypted
+		}
+	}
+	if kmsKey != "" || deepResearchFirstNonEmptyAttr(attrs, "sseAlgorithm", "serverSideEncryption", "encryption") != "" {
+		return securityPostureStateEncrypted
+	}
+	switch storeType {
+	case "gcs", "r2":
+		// GCS and R2 always encrypt at rest with provider-managed keys.
+		return securityPostureStateEncrypted
+	case "s3":
+		// S3 applies SSE-S3 by default to every new object since January 2023.
+		return securityPostureStateEncrypted
+	}
+	return securityPostureStateUnknown
+}
+
+func securityPostureKeyType(atRest string, attrs map[string]interface{}, kmsKey string) string {
+	if atRest == securityPostureStateUnencrypted {
+		return securityPostureKeyNone
+	}
+	if kmsKey != "" && !strings.Contains(strings.ToLower(kmsKey), "alias/aws/") {
+		return securityPostureKeyCMK
+	}
+	if algorithm := strings.ToLower(deepResearchFirstNonEmptyAttr(attrs, "sseAlgorithm", "serverSideEncryption")); strings.Contains(algorithm, "kms") && kmsKey == "" {
+		return securityPostureKeyManaged
+	}
+	if atRest == securityPostureStateEncrypted {
+		return securityPostureKeyManaged
+	}
+	return securityPostureStateUnknown
+}
+
+func securityPostureInTransit(storeType string, attrs map[string]interface{}) string {
+	for _, key := range []string{"enforceSSL", "requireSSL", "forceSSL", "secureTransport", "requireSecureTransport", "sslEnforced"} {
+		if value, ok := deepResearchBoolAttr(attrs, key); ok {
+			if value {
+				return securityPostureStateEnforced
+			}
+			return securityPostureStateOptional
+		}
+	}
+	switch storeType {
+	case "gcs", "r2":
+		// Both APIs are HTTPS-only.
+		return securityPostureStateEnforced
+	case "ebs", "pvc":
+		// Block volumes are not addressed over the network by clients.
+		return "n/a"
+	}
+	return securityPostureStateUnknown
+}
+
+func securityPosturePublicExposure(storeType string, resource deepResearchResource) string {
+	attrs := resource.Attributes
+	for _, key := range []string{"publiclyAccessible", "public", "publicAccess", "isPublic"} {
+		if value, ok := deepResearchBoolAttr(attrs, key); ok {
+			if value {
+				return securityPostureStatePublic
+			}
+			return securityPostureStatePrivate
+		}
+	}
+	if value, ok := deepResearchBoolAttr(attrs, "blockPublicAccess"); ok {
+		if value {
+			return securityPostureStatePrivate
+		}
+		return securityPostureStatePublic
+	}
+	acl := strings.ToLower(deepResearchFirstNonEmptyAttr(attrs, "acl", "iamMembers", "publicAccessPrevention", "publicUrl", "r2DevUrl"))
+	switch {
+	case strings.Contains(acl, "public-read") || strings.Contains(acl, "allusers") || strings.Contains(acl, "allauthenticatedusers") || strings.Contains(acl, "r2.dev"):
+		return securityPostureStatePublic
+	case strings.Contains(acl, "enforced") || acl == "private":
+		return securityPostureStatePrivate
+	}
+	if storeType == "rds" && deepResearchHasExternalAddress(resource) {
+		return securityPostureStatePublic
+	}
+	if storeType == "ebs" || storeType == "pvc" {
+		return securityPostureStatePrivate
+	}
+	return securityPostureStateUnknown
+}
+
+// securityPostureSensitive reports whether a store is tagged as holding
+// regulated data, where provider-managed keys usually fall short of policy.
+func securityPostureSensitive(resource deepResearchResource) bool {
+	value := strings.ToLower(deepResearchTagValue(resource.Tags, "data-classification", "dataClassification", "classification", "compliance"))
+	return deepResearchContainsAny(value, "pci", "phi", "hipaa", "confidential", "restricted", "sensitive")
+}
+
+func securityPostureControlsFor(gaps []string, framework string) []string {
+	controls := []string{}
+	for _, gap := range gaps {
+		byFramework := securityPostureControls[gap]
+		frameworks := make([]string, 0, len(byFramework))
+		for name := range byFramework {
+			if framework == "" || name == framework {
+				frameworks = append(frameworks, name)
+			}
+		}
+		sort.Strings(frameworks)
+		for _, name := range frameworks {
+			controls = append(controls, byFramework[name]...)
+		}
+	}
+	return uniqueNonEmptyStrings(controls)
+}
+
+func printSecurityPostureMatrix(out io.Writer, matrix securityPostureMatrix) {
+	for _, warning := range matrix.Warnings {
+		fmt.Fprintf(out, "Warning: %s\n", warning)
+	}
+	s := matrix.Summary
+	fmt.Fprintf(out, "Data stores: %d (unencrypted %d, transit gaps %d, public %d, CMK %d)\n",
+		s.DataStores, s.Unencrypted, s.TransitGaps, s.PublicStores, s.CustomerKeys)
+	if matrix.Framework != "" {
+		fmt.Fprintf(out, "Framework: %s\n", matrix.Framework)
+	}
+	fmt.Fprintln(out)
+
+	if len(matrix.Rows) == 0 {
+		fmt.Fprintln(out, "No data stores found in the estate snapshot.")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tPROVIDER\tNAME\tAT REST\tIN TRANSIT\tKEY\tEXPOSURE\tCONTROLS")
+	fmt.Fprintln(w, "----\t--------\t----\t-------\t----------\t---\t--------\t--------")
+	for _, row := range matrix.Rows {
+		controls := "-"
+		if len(row.Controls) > 0 {
+			controls = strings.Join(row.Controls, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			strings.ToUpper(row.StoreType), row.Provider, truncate(row.Name, 40),
+			row.AtRest, row.InTransit, row.KeyType, row.PublicExposure, controls,
+		)
+	}
+	w.Flush()
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBuildSecurityPostureMatrixClassifiesStores(t *testing.T) {
+	resources := []deepResearchResource{
+		{ID: "arn:aws:s3:::logs", Type: "s3", Name: "logs", Attributes: map[string]interface{}{"blockPublicAccess": false, "enforceSSL": false}},
+		{ID: "db-1", Type: "rds", Name: "orders", Attributes: map[string]interface{}{"storageEncrypted": false, "publiclyAccessible": true}},
+		{ID: "vol-1", Type: "ebs", Name: "data", Attributes: map[string]interface{}{"encrypted": true, "kmsKeyId": "arn:aws:kms:us-east-1:1:key/abc"}},
+		{ID: "projects/p/buckets/b", Type: "gcs_bucket", Name: "b", Attributes: map[string]interface{}{"publicAccessPrevention": "enforced"}},
+		{ID: "r2-1", Type: "cloudflare_r2_bucket", Name: "assets", Attributes: map[string]interface{}{"r2DevUrl": "https://pub-1.r2.dev"}},
+		{ID: "i-1", Type: "ec2", Name: "web"},
+	}
+
+	matrix := buildSecurityPostureMatrix(resources, "")
+	if matrix.Summary.DataStores != 5 {
+		t.Fatalf("expected 5 data stores, got %d (%#v)", matrix.Summary.DataStores, matrix.Rows)
+	}
+	if matrix.Summary.Unencrypted != 1 || matrix.Summary.PublicStores != 3 || matrix.Summary.CustomerKeys != 1 {
+		t.Fatalf("unexpected summary: %#v", matrix.Summary)
+	}
+
+	rows := map[string]securityPostureRow{}
+	for _, row := range matrix.Rows {
+		rows[row.ResourceID] = row
+	}
+	if row := rows["db-1"]; row.AtRest != securityPostureStateUnencrypted || row.KeyType != securityPostureKeyNone || row.PublicExposure != securityPostureStatePublic {
+		t.Fatalf("unexpected rds row: %#v", row)
+	}
+	if row := rows["vol-1"]; row.KeyType != securityPostureKeyCMK || len(row.Gaps) != 0 {
+		t.Fatalf("expected CMK ebs row without gaps, got %#v", row)
+	}
+	if row := rows["projects/p/buckets/b"]; row.AtRest != securityPostureStateEncrypted || row.PublicExposure != securityPostureStatePrivate {
+		t.Fatalf("unexpected gcs row: %#v", row)
+	}
+	if row := rows["arn:aws:s3:::logs"]; row.InTransit != securityPostureStateOptional || !strings.Contains(strings.Join(row.Controls, ","), "PCI DSS 4.2") {
+		t.Fatalf("expected s3 transit gap mapped to controls, got %#v", row)
+	}
+	if matrix.Rows[0].ResourceID != "db-1" && matrix.Rows[0].ResourceID != "arn:aws:s3:::logs" {
+		t.Fatalf("expected rows with most gaps first, got %#v", matrix.Rows[0])
+	}
+}
+
+func TestBuildSecurityPostureMatrixFrameworkFilter(t *testing.T) {
+	resources := []deepResearchResource{
+		{ID: "db-1", Type: "rds", Attributes: map[string]interface{}{"storageEncrypted": false}},
+	}
+	matrix := buildSecurityPostureMatrix(resources, "hipaa")
+	if got := matrix.Rows[0].Controls; len(got) != 1 || got[0] != "HIPAA 164.312(a)(2)(iv)" {
+		t.Fatalf("expected only HIPAA controls, got %#v", got)
+	}
+}
+
+func TestBuildSecurityPostureMatrixFlagsManagedKeysOnSensitiveData(t *testing.T) {
+	resources := []deepResearchResource{
+		{ID: "vol-2", Type: "ebs", Tags: map[string]string{"data-classification": "PCI"}, Attributes: map[string]interface{}{"encrypted": true}},
+	}
+	row := buildSecurityPostureMatrix(resources, "").Rows[0]
+	if row.KeyType != securityPostureKeyManaged || len(row.Gaps) != 1 || row.Gaps[0] != "key-management" {
+		t.Fatalf("expected key-management gap, got %#v", row)
+	}
+}
+
+func TestPrintSecurityPostureMatrix(t *testing.T) {
+	var buf bytes.Buffer
+	printSecurityPostureMatrix(&buf, securityPostureMatrix{})
+	if !strings.Contains(buf.String(), "No data stores found") {
+		t.Fatalf("expected empty message, got %q", buf.String())
+	}
+
+	buf.Reset()
+	printSecurityPostureMatrix(&buf, buildSecurityPostureMatrix([]deepResearchResource{
+		{ID: "db-1", Type: "rds", Name: "orders", Attributes: map[string]interface{}{"storageEncrypted": false}},
+	}, ""))
+	out := buf.String()
+	for _, want := range []string{"Data stores: 1", "AT REST", "RDS", "orders", "unencrypted", "SOC 2 CC6.1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, out)
+		}
+	}
+}
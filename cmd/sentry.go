@@ -2,12 +2,16 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/bgdnvk/clanker/internal/ai"
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/bgdnvk/clanker/internal/planstore"
 	"github.com/bgdnvk/clanker/internal/sentry"
+	"github.com/bgdnvk/clanker/internal/sentry/triage"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"golang.org/x/sync/errgroup"
@@ -26,7 +30,14 @@ Examples:
   clanker sentry ask "what's the worst error today?"
   clanker sentry ask "any new errors since the last release?"
   clanker sentry ask "are any monitors failing?"
-  clanker sentry ask "show me unresolved issues in prod" --environment prod`,
+  clanker sentry ask "show me unresolved issues in prod" --environment prod
+  clanker sentry ask "what are the top crashers this week"
+  clanker sentry ask "release health of project backend"
+  clanker sentry ask "resolve BACKEND-42 and BACKEND-57"
+
+Top issues, issue searches by release or environment, and release health are
+answered straight from the Sentry API. Resolve, ignore and assign print a plan
+to apply with clanker ask --apply.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runSentryAsk,
 }
@@ -98,6 +109,44 @@ func runSentryAsk(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
+	return askSentry(ctx, client, org, project, sentryAskEnvironment, sentryAskAIProfile, question, debug)
+}
+
+// handleSentryQuery answers a question ask routed to Sentry, with the auth
+// token, org and default project from config or the environment.
+func handleSentryQuery(ctx context.Context, question string, debug bool) error {
+	if debug {
+		fmt.Println("Delegating query to Sentry agent...")
+	}
+	authToken, org := sentry.ResolveAuthToken(), sentry.ResolveOrgSlug()
+	if authToken == "" {
+		return fmt.Errorf("sentry auth token is required (set SENTRY_AUTH_TOKEN or sentry.auth_token in config)")
+	}
+	if org == "" {
+		return fmt.Errorf("sentry org slug is required (set SENTRY_ORG or sentry.org_slug in config)")
+	}
+	client, err := sentry.NewClient(authToken, org, sentry.ResolveHost(), debug)
+	if err != nil {
+		return fmt.Errorf("create sentry client: %w", err)
+	}
+	return askSentry(ctx, client, org, sentry.ResolveDefaultProject(), "", "", question, debug)
+}
+
+// askSentry answers question about org. Top crashers, issue searches and
+// release health are answered directly and resolve, ignore and assign are
+// printed as plans; anything else goes to the LLM with the Sentry data the
+// question calls for.
+func askSentry(ctx context.Context, client *sentry.Client, org, project, environment, aiProfile, question string, debug bool) error {
+	if req := triage.ParseOpRequest(question); req.Op != "" {
+		if req.Project == "" {
+			req.Project = project
+		}
+		if req.Environment == "" {
+			req.Environment = environment
+		}
+		return handleSentryOp(ctx, client, org, req, question)
+	}
+
 	history := sentry.NewConversationHistory(org)
 	if err := history.Load(); err != nil && debug {
 		fmt.Printf("[debug] load history: %v\n", err)
@@ -109,14 +158,13 @@ func runSentryAsk(cmd *cobra.Command, args []string) error {
 		fmt.Printf("[debug] gather status: %v\n", err)
 	}
 
-	dataContext, err := gatherSentryContext(ctx, client, question, project, sentryAskEnvironment, debug)
+	dataContext, err := gatherSentryContext(ctx, client, question, project, environment, debug)
 	if err != nil && debug {
 		fmt.Printf("[debug] gather context: %v\n", err)
 	}
 
 	prompt := buildSentryPrompt(question, dataContext, history.GetRecentContext(5), history.GetAccountStatusContext())
 
-	aiProfile := sentryAskAIProfile
 	if aiProfile == "" {
 		aiProfile = viper.GetString("ai.default_provider")
 	}
@@ -138,6 +186,97 @@ func runSentryAsk(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// handleSentryOp runs a Sentry read, or prints the plan for a resolve,
+// ignore or assign. Plans are never applied here.
+func handleSentryOp(ctx context.Context, client *sentry.Client, org string, req triage.OpRequest, question string) error {
+	ops := triage.NewOps(client, org, time.Now())
+	if !req.Op.Mutates() {
+		out, err := ops.Read(ctx, req)
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+		if !strings.HasSuffix(out, "\n") {
+			fmt.Println()
+		}
+		return nil
+	}
+
+	issues, err := ops.Issues(ctx, req.Issues)
+	if err != nil {
+		return err
+	}
+	opPlan, err := triage.BuildOpPlan(req, org, issues, question, time.Now())
+	if err != nil {
+		return err
+	}
+	planJSON, err := json.MarshalIndent(opPlan.Plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format plan: %w", err)
+	}
+	fmt.Printf("%s\n\n", opPlan.Plan.Summary)
+	fmt.Println(string(planJSON))
+	if err := runPlanGeneratedHooks("ask sentry", planJSON); err != nil {
+		return err
+	}
+	saveGeneratedPlan(planstore.KindMaker, opPlan.Plan.Provider, "ask sentry", question, opPlan.Plan.Summary, planJSON)
+	for _, note := range opPlan.Notes {
+		fmt.Printf("\n// %s", note)
+	}
+	fmt.Println("\n// To apply this plan, run:")
+	fmt.Println("// clanker ask --apply --plan-file <save-above-to-file.json>")
+	return nil
+}
+
+// sentryErrorTracker gives the k8s SRE sub-agent the Sentry issues a
+// workload's pods reported, so a diagnosis shows the application errors
+// behind a failing deployment
+type sentryErrorTracker struct {
+	ops *triage.Ops
+}
+
+// newSentryErrorTracker returns nil unless a Sentry auth token and org are
+// configured, in which case diagnoses simply go without Sentry.
+func newSentryErrorTracker(debug bool) sre.ErrorTracker {
+	authToken, org := sentry.ResolveAuthToken(), sentry.ResolveOrgSlug()
+	if authToken == "" || org == "" {
+		return nil
+	}
+	client, err := sentry.NewClient(authToken, org, sentry.ResolveHost(), debug)
+	if err != nil {
+		if debug {
+			fmt.Printf("[debug] sentry error tracker disabled: %v\n", err)
+		}
+		return nil
+	}
+	return &sentryErrorTracker{ops: triage.NewOps(client, org, time.Now())}
+}
+
+// RelatedErrors returns the five most frequent unresolved issues of the
+// last day from hosts named after the workload. Sentry SDKs report the pod
+// name as server_name, and pod names start with their workload's name.
+func (t *sentryErrorTracker) RelatedErrors(ctx context.Context, resourceType, name, namespace string) ([]sre.TrackedError, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	issues, err := t.ops.RelatedIssues(ctx, name, 5)
+	if err != nil {
+		return nil, err
+	}
+	tracked := make([]sre.TrackedError, 0, len(issues))
+	for _, i := range issues {
+		tracked = append(tracked, sre.TrackedError{
+			Source:   "sentry",
+			ID:       i.ShortID,
+			Title:    i.Title,
+			Events:   i.Count,
+			Users:    i.UserCount,
+			LastSeen: i.LastSeen,
+			Link:     i.Permalink,
+		})
+	}
+	return tracked, nil
+}
+
 // gatherSentryContext fetches Sentry data relevant to the question. Sections
 // are picked by keyword routing — Sentry's search syntax is rich enough that
 // we mostly forward `is:unresolved`-style filters and let the LLM correlate.
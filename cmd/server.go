@@ -29,17 +29,33 @@ func init() {
 		geminiKey              string
 		localModelInferenceURL string
 		noThinking             bool
+		maxConcurrent          int
 	)
 
 	serverCmd := &cobra.Command{
-		Use:   "server",
-		Short: "Run the Clanker HTTP API server",
+		Use:     "server",
+		Aliases: []string{"serve"},
+		Short:   "Run the Clanker HTTP API server",
 		Long: `Start the HTTP API server that wraps the Clanker agent.
 
 This is the gateway for the Clanker web dashboard. Inventory + maker
 apply + plan-generation endpoints all live here.
 
-Auth: pass --token or set CLANKER_API_TOKEN. The server refuses to start
+The ask pipeline is served too, so a backend can drive clanker without
+shelling out per request:
+
+  POST /api/v1/ask                 {"question", "provider", "agent", "profile", "stream"}
+  POST /api/v1/plans               same body plus "destroyer"; returns the plan and its stored id
+  POST /api/v1/plans/{id}/apply    {"profile", "stream"}; keeps running if the client goes away
+  GET  /api/v1/route?question=...  the routing decision of ask --route-only
+
+Each request runs one clanker process; --max-concurrent caps how many run
+at once and further requests get 429. With "stream": true the response is
+NDJSON: one {"stream","text"} event per output line, then a {"done"} event
+with the result.
+
+Auth: pass --token or set CLANKER_API_TOKEN. Clients send it as
+"Authorization: Bearer <token>" or "X-API-Key: <token>". The server refuses to start
 without one — POST /api/v1/maker/apply can mutate real cloud resources, so
 unauthenticated startup is gated behind an explicit --insecure flag.
 
@@ -71,35 +87,44 @@ Examples:
 
 			// Push AI config into viper so api handlers building ai.NewClient
 			// pick the same values the CLI does. Empty flags leave existing
-			// config (from ~/.clanker.yaml) untouched.
-			if strings.TrimSpace(aiProfile) != "" {
-				viper.Set("ai.default_provider", aiProfile)
-			}
-			if strings.TrimSpace(openaiKey) != "" {
-				viper.Set("ai.providers.openai.api_key", openaiKey)
-			}
-			if strings.TrimSpace(openaiModel) != "" {
-				viper.Set("ai.providers.openai.model", openaiModel)
-			}
-			if strings.TrimSpace(anthropicKey) != "" {
-				viper.Set("ai.providers.anthropic.api_key", anthropicKey)
-			}
-			if strings.TrimSpace(geminiKey) != "" {
-				viper.Set("ai.providers.gemini-api.api_key", geminiKey)
-			}
-			if strings.TrimSpace(localModelInferenceURL) != "" {
-				viper.Set("ai.providers.openai.local_model_inference_url", strings.TrimSpace(localModelInferenceURL))
+			// config (from ~/.clanker.yaml) untouched. The ask endpoints run
+			// clanker as a child process, which gets the same values through
+			// its environment: viper.AutomaticEnv reads a key from the
+			// upper-cased variable of the same name, keeping API keys off the
+			// child's command line.
+			var childEnv []string
+			set := func(key, value string) {
+				value = strings.TrimSpace(value)
+				if value == "" {
+					return
+				}
+				viper.Set(key, value)
+				childEnv = append(childEnv, strings.ToUpper(key)+"="+value)
 			}
+			set("ai.default_provider", aiProfile)
+			set("ai.providers.openai.api_key", openaiKey)
+			set("ai.providers.openai.model", openaiModel)
+			set("ai.providers.anthropic.api_key", anthropicKey)
+			set("ai.providers.gemini-api.api_key", geminiKey)
+			set("ai.providers.openai.local_model_inference_url", localModelInferenceURL)
 			if noThinking {
 				viper.Set("ai.providers.openai.chat_template_kwargs", map[string]interface{}{"enable_thinking": false})
 			}
 
+			var childFlags []string
+			if cfgFile != "" {
+				childFlags = append(childFlags, "--config", cfgFile)
+			}
+
 			srv := api.New(api.Config{
-				Addr:       addr,
-				Token:      resolved,
-				Insecure:   insecure,
-				CORSOrigin: corsOrigin,
-				Debug:      debug,
+				Addr:          addr,
+				Token:         resolved,
+				Insecure:      insecure,
+				CORSOrigin:    corsOrigin,
+				Debug:         debug,
+				MaxConcurrent: maxConcurrent,
+				Env:           childEnv,
+				Flags:         childFlags,
 			}, log.New(os.Stderr, "", log.LstdFlags))
 
 			ctx, cancel := context.WithCancel(context.Background())
@@ -121,6 +146,7 @@ Examples:
 	serverCmd.Flags().BoolVar(&insecure, "insecure", false, "Allow startup without a bearer token. NEVER use on a publicly reachable address — /api/v1/maker/apply mutates real cloud resources.")
 	serverCmd.Flags().StringVar(&corsOrigin, "cors-origin", "", "Value for Access-Control-Allow-Origin (defaults to http://localhost:4173 — pass an explicit value for non-localhost dashboards; \"*\" allowed but discouraged)")
 	serverCmd.Flags().BoolVar(&debug, "server-debug", false, "Log every request, not just errors")
+	serverCmd.Flags().IntVar(&maxConcurrent, "max-concurrent", 4, "Most ask, plan, apply and route requests to run at once")
 
 	// LLM provider flags — push into viper so the plan-generation endpoint
 	// has the same options the CLI exposes.
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/aws"
+	"github.com/bgdnvk/clanker/internal/aws/ssm"
+	"github.com/bgdnvk/clanker/internal/routing"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// sessionManagerPlugin is the binary aws ssm start-session hands the
+// session to
+const sessionManagerPlugin = "session-manager-plugin"
+
+// newAWSSSMCmd builds `clanker aws ssm`, Session Manager shells and
+// SendCommand runs as an alternative to SSH, including for kubeadm nodes
+func newAWSSSMCmd() *cobra.Command {
+	ssmCmd := &cobra.Command{
+		Use:   "ssm",
+		Short: "Reach EC2 instances through SSM Session Manager instead of SSH",
+		Long: `Open shells and run commands on EC2 instances through AWS Systems Manager.
+Instances need the SSM agent running and an instance profile with
+AmazonSSMManagedInstanceCore; no inbound port or key pair is needed.`,
+	}
+
+	connectCmd := &cobra.Command{
+		Use:   "connect <instance-id|name>",
+		Short: "Open a Session Manager shell on an instance",
+		Example: `  clanker aws ssm connect i-0abc12345678def00
+  clanker aws ssm connect bastion --print`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			printOnly, _ := cmd.Flags().GetBool("print")
+			ctx := cmd.Context()
+			client, agent, err := newSSMAgent(ctx, profile, viper.GetBool("debug"))
+			if err != nil {
+				return err
+			}
+			return ssmConnect(ctx, client, agent, ssmSelectorFor(args[0]), printOnly)
+		},
+	}
+	connectCmd.Flags().String("profile", "", "AWS profile to use")
+	connectCmd.Flags().Bool("print", false, "Print the aws ssm start-session command instead of starting it")
+	_ = connectCmd.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	runCmd := &cobra.Command{
+		Use:   "run -- <command>",
+		Short: "Run a shell command on matching instances and collect the output",
+		Example: `  clanker aws ssm run --tag role=web -- systemctl status nginx
+  clanker aws ssm run --cluster dev -- "sudo kubeadm certs check-expiration"
+  clanker aws ssm run --instance i-0abc12345678def00 --yes -- uptime`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			ids, _ := cmd.Flags().GetStringSlice("instance")
+			tags, _ := cmd.Flags().GetStringSlice("tag")
+			cluster, _ := cmd.Flags().GetString("cluster")
+			yes, _ := cmd.Flags().GetBool("yes")
+
+			sel := ssm.Selector{InstanceIDs: ids, Cluster: strings.TrimSpace(cluster)}
+			for _, t := range tags {
+				tag, err := ssm.ParseTag(t)
+				if err != nil {
+					return err
+				}
+				sel.Tags = append(sel.Tags, tag)
+			}
+			if sel.Empty() {
+				return fmt.Errorf("choose instances with --instance, --tag or --cluster")
+			}
+			command := strings.TrimSpace(strings.Join(args, " "))
+
+			ctx := cmd.Context()
+			_, agent, err := newSSMAgent(ctx, profile, viper.GetBool("debug"))
+			if err != nil {
+				return err
+			}
+			return ssmRun(ctx, agent, sel, command, !yes)
+		},
+	}
+	runCmd.Flags().String("profile", "", "AWS profile to use")
+	runCmd.Flags().StringSlice("instance", nil, "Instance ID to run on (repeatable)")
+	runCmd.Flags().StringSlice("tag", nil, "Only instances with this tag, as key=value (repeatable)")
+	runCmd.Flags().String("cluster", "", "Only the nodes of this kubeadm cluster")
+	runCmd.Flags().Bool("yes", false, "Run without asking for confirmation")
+	_ = runCmd.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+
+	ssmCmd.AddCommand(connectCmd, runCmd)
+	return ssmCmd
+}
+
+// ssmSelectorFor selects an instance by ID, or by Name tag otherwise
+func ssmSelectorFor(target string) ssm.Selector {
+	if strings.HasPrefix(target, "i-") {
+		return ssm.Selector{InstanceIDs: []string{target}}
+	}
+	return ssm.Selector{Tags: []ssm.Tag{{Key: "Name", Value: target}}}
+}
+
+func newSSMAgent(ctx context.Context, profile string, debug bool) (*aws.Client, *ssm.Agent, error) {
+	targetProfile := resolveAWSProfile(profile)
+	client, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create AWS client with profile %s: %w", targetProfile, err)
+	}
+	return client, ssm.NewAgent(client.ExecCLI, debug), nil
+}
+
+// ssmConnect opens a Session Manager shell on the one instance sel matches.
+// With printOnly, or without the session manager plugin, it prints the
+// command to run instead.
+func ssmConnect(ctx context.Context, client *aws.Client, agent *ssm.Agent, sel ssm.Selector, printOnly bool) error {
+	instances, err := agent.Instances(ctx, sel)
+	if err != nil {
+		return err
+	}
+	if len(instances) > 1 {
+		labels := make([]string, len(instances))
+		for i, inst := range instances {
+			labels[i] = inst.Label()
+		}
+		return fmt.Errorf("%s matches %d instances (%s); connect by instance ID", sel, len(instances), strings.Join(labels, ", "))
+	}
+	instance := instances[0]
+	if !instance.Managed {
+		return ssm.NotManagedError(instance)
+	}
+
+	session := client.CLICommand(ctx, ssm.SessionArgs(instance.ID))
+	_, pluginErr := exec.LookPath(sessionManagerPlugin)
+	if printOnly || pluginErr != nil {
+		if pluginErr != nil && !printOnly {
+			fmt.Fprintf(os.Stderr, "%s is not installed; see https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html\n", sessionManagerPlugin)
+		}
+		fmt.Printf("Connect to %s with:\n  %s\n", instance.Label(), shellJoin(session.Args))
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Starting session on %s...\n", instance.Label())
+	session.Stdin, session.Stdout, session.Stderr = os.Stdin, os.Stdout, os.Stderr
+	// Ctrl-C belongs to the remote shell, not to clanker
+	signal.Ignore(os.Interrupt)
+	defer signal.Reset(os.Interrupt)
+	if err := session.Run(); err != nil {
+		return fmt.Errorf("session on %s ended with error: %w", instance.Label(), err)
+	}
+	return nil
+}
+
+// ssmRun runs command on the instances sel matches and prints each one's
+// output. With confirm it lists the targets and asks first.
+func ssmRun(ctx context.Context, agent *ssm.Agent, sel ssm.Selector, command string, confirm bool) error {
+	instances, err := agent.Instances(ctx, sel)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Command: %s\nInstances (%s):\n", command, sel)
+	for _, inst := range instances {
+		note := ""
+		if !inst.Managed {
+			note = "  (SSM agent not online, skipped)"
+		}
+		fmt.Printf("  - %s%s\n", inst.Label(), note)
+	}
+	if confirm {
+		fmt.Print("Run this command on these instances? [y/N]: ")
+		var response string
+		fmt.Scanln(&response)
+		if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+	fmt.Println()
+
+	result, err := agent.Run(ctx, instances, command)
+	if err != nil {
+		return err
+	}
+	fmt.Print(result.Format())
+	if failed := len(result.Invocations) - result.Succeeded(); failed > 0 {
+		return fmt.Errorf("command failed on %d of %d instances", failed, len(result.Invocations))
+	}
+	return nil
+}
+
+// ssmRunCommandLine is the clanker aws ssm run invocation for a request
+func ssmRunCommandLine(req ssm.Request, profile string) string {
+	args := []string{"clanker", "aws", "ssm", "run"}
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
+	for _, id := range req.Selector.InstanceIDs {
+		args = append(args, "--instance", id)
+	}
+	for _, t := range req.Selector.Tags {
+		args = append(args, "--tag", t.String())
+	}
+	if req.Selector.Cluster != "" {
+		args = append(args, "--cluster", req.Selector.Cluster)
+	}
+	args = append(args, "--yes", "--", req.Command)
+	return shellJoin(args)
+}
+
+// handleAWSSSMQuery answers "connect to instance i-abc" and "run X on all
+// instances tagged role=web". Commands run only after an interactive
+// confirmation; otherwise the equivalent clanker aws ssm command is printed.
+func handleAWSSSMQuery(ctx context.Context, question string, debug bool, profile string) error {
+	req, ok := ssm.ParseRequest(question)
+	if !ok {
+		return fmt.Errorf("name an instance to connect to, or a command and the instances to run it on (e.g. run `uptime` on all instances tagged role=web)")
+	}
+	if debug {
+		fmt.Printf("Delegating query to AWS SSM agent (profile %s)...\n", resolveAWSProfile(profile))
+	}
+	interactive := isStdinTerminal()
+	if req.Action == ssm.RunCommand && !interactive {
+		fmt.Printf("Not running %q without confirmation. To run it on %s:\n  %s\n", req.Command, req.Selector, ssmRunCommandLine(req, profile))
+		return nil
+	}
+
+	client, agent, err := newSSMAgent(ctx, profile, debug)
+	if err != nil {
+		return err
+	}
+	if req.Action == ssm.Connect {
+		return ssmConnect(ctx, client, agent, req.Selector, !interactive)
+	}
+	return ssmRun(ctx, agent, req.Selector, req.Command, true)
+}
+
+// shouldRouteToAWSSSMAgent reports whether a question asks to connect to an
+// EC2 instance or run a command on instances
+func shouldRouteToAWSSSMAgent(question string) bool {
+	if !ssm.IsSSMQuestion(question) {
+		return false
+	}
+	svcCtx := routing.InferContext(question)
+	return !svcCtx.GCP && !svcCtx.Azure && !svcCtx.DigitalOcean && !svcCtx.Hetzner && !svcCtx.Oracle
+}
+
+// shellJoin quotes args for a POSIX shell
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && strings.IndexFunc(a, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@,", r))
+		}) < 0 {
+			quoted[i] = a
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/aws/ssm"
+)
+
+func TestSSMRunCommandLine(t *testing.T) {
+	req, ok := ssm.ParseRequest("run \"systemctl restart nginx\" on all instances tagged role=web")
+	if !ok {
+		t.Fatal("expected an SSM request")
+	}
+	want := "clanker aws ssm run --profile prod --tag role=web --yes -- 'systemctl restart nginx'"
+	if got := ssmRunCommandLine(req, "prod"); got != want {
+		t.Errorf("ssmRunCommandLine = %s, want %s", got, want)
+	}
+}
+
+func TestShellJoin(t *testing.T) {
+	got := shellJoin([]string{"aws", "ssm", "start-session", "--target", "i-0abc", "--profile", "it's"})
+	want := `aws ssm start-session --target i-0abc --profile 'it'\''s'`
+	if got != want {
+		t.Errorf("shellJoin = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+	"github.com/bgdnvk/clanker/internal/maker"
+	"github.com/bgdnvk/clanker/internal/terraform/emit"
+)
+
+const defaultEmitDir = "clanker-terraform"
+
+// emitPlanTerraform writes an approved maker or K8s cluster plan to dir as a
+// Terraform module instead of running its commands
+func emitPlanTerraform(rawPlan, format, dir string, debug bool) error {
+	if format = strings.ToLower(strings.TrimSpace(format)); format != "terraform" {
+		return fmt.Errorf("unsupported --emit format %q (supported: terraform)", format)
+	}
+	if strings.TrimSpace(dir) == "" {
+		dir = defaultEmitDir
+	}
+
+	makerPlan, opts, err := planForEmit(rawPlan)
+	if err != nil {
+		return err
+	}
+	module, err := emit.Render(makerPlan, opts)
+	if err != nil {
+		return err
+	}
+	paths, err := module.Write(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("[terraform] wrote %d resource(s) to %s\n", module.Resources, dir)
+	for _, path := range paths {
+		fmt.Printf("  %s\n", path)
+	}
+	for _, skipped := range module.Skipped {
+		fmt.Printf("[terraform] not rendered: %s\n", skipped)
+	}
+	if debug {
+		fmt.Printf("[terraform] review with: terraform -chdir=%s init && terraform -chdir=%s plan\n", dir, dir)
+	}
+	return nil
+}
+
+// planForEmit reads rawPlan as a K8s cluster plan (steps) or a maker plan
+// (commands). K8s workload plans have no Terraform form; --gitops covers them.
+func planForEmit(rawPlan string) (*maker.Plan, emit.Options, error) {
+	var clusterPlan plan.K8sPlan
+	if err := json.Unmarshal([]byte(rawPlan), &clusterPlan); err == nil && len(clusterPlan.Steps) > 0 {
+		mp := clusterPlan.ToMakerPlan(clusterPlan.Summary)
+		makerPlan := &maker.Plan{Version: mp.Version, CreatedAt: mp.CreatedAt, Summary: mp.Summary, Notes: mp.Notes}
+		for _, c := range mp.Commands {
+			makerPlan.Commands = append(makerPlan.Commands, maker.Command{Args: c.Args, Reason: c.Reason, Produces: c.Produces})
+		}
+		return makerPlan, emit.Options{Region: clusterPlan.Region}, nil
+	}
+	var workloadPlan k8s.K8sPlan
+	if err := json.Unmarshal([]byte(rawPlan), &workloadPlan); err == nil && (len(workloadPlan.HelmCmds) > 0 || len(workloadPlan.KubectlCmds) > 0 || len(workloadPlan.Manifests) > 0) {
+		return nil, emit.Options{}, fmt.Errorf("--emit terraform renders cluster and cloud resource plans; use --gitops for K8s workload plans")
+	}
+	makerPlan, err := maker.ParsePlan(rawPlan)
+	if err != nil {
+		return nil, emit.Options{}, fmt.Errorf("invalid plan: %w", err)
+	}
+	return makerPlan, emit.Options{}, nil
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/aws"
+	tfclient "github.com/bgdnvk/clanker/internal/terraform"
+	"github.com/spf13/cobra"
+)
+
+// terraformDriftTimeout bounds a drift check: a full plan plus the live
+// AWS inventory
+const terraformDriftTimeout = 5 * time.Minute
+
+var terraformStateCmd = &cobra.Command{
+	Use:   "state [workspace-or-path]",
+	Short: "List the resources in Terraform state, or show one",
+	Long: `List the resources in a workspace's state grouped by type, from state list.
+With --address, also print that resource's attributes from state show.
+
+Examples:
+  clanker terraform state prod
+  clanker terraform state prod --address aws_instance.web`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace, _ := cmd.Flags().GetString("workspace")
+		if len(args) > 0 {
+			workspace = args[0]
+		}
+		tool, _ := cmd.Flags().GetString("tool")
+		address, _ := cmd.Flags().GetString("address")
+		format, _ := cmd.Flags().GetString("format")
+
+		client, err := tfclient.NewClientWithTool(workspace, tool)
+		if err != nil {
+			return err
+		}
+		inv, err := client.Inventory(cmd.Context(), address)
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(format, "json") {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(inv)
+		}
+		fmt.Print(inv.Format())
+		return nil
+	},
+}
+
+var terraformDriftCmd = &cobra.Command{
+	Use:   "drift [workspace-or-path]",
+	Short: "Check for drift and for AWS resources managed outside Terraform",
+	Long: `Run plan -detailed-exitcode to see whether infrastructure has drifted from the
+configuration, then compare the IDs and ARNs in state against the account's
+tagged AWS resources to report the ones Terraform does not manage. Skip the
+AWS comparison with --no-aws.
+
+Examples:
+  clanker terraform drift prod
+  clanker terraform drift prod --profile prod-admin
+  clanker terraform drift ./infra --no-aws`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspace, _ := cmd.Flags().GetString("workspace")
+		if len(args) > 0 {
+			workspace = args[0]
+		}
+		tool, _ := cmd.Flags().GetString("tool")
+		profile, _ := cmd.Flags().GetString("profile")
+		noAWS, _ := cmd.Flags().GetBool("no-aws")
+		maxLines, _ := cmd.Flags().GetInt("max-lines")
+		format, _ := cmd.Flags().GetString("format")
+		debug, _ := cmd.Flags().GetBool("debug")
+
+		client, err := tfclient.NewClientWithTool(workspace, tool)
+		if err != nil {
+			return err
+		}
+		var live tfclient.LiveInventory
+		if !noAWS {
+			live = awsLiveInventory(profile, debug)
+		}
+		ctx, cancel := context.WithTimeout(cmd.Context(), terraformDriftTimeout)
+		defer cancel()
+		check, err := client.CheckDrift(ctx, live, maxLines)
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(format, "json") {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(check)
+		}
+		fmt.Print(check.Format())
+		return nil
+	},
+}
+
+func init() {
+	terraformCmd.AddCommand(terraformStateCmd, terraformDriftCmd)
+	for _, c := range []*cobra.Command{terraformStateCmd, terraformDriftCmd} {
+		c.Flags().String("workspace", "", "Configured workspace name or local path")
+		c.Flags().String("tool", "", "IaC binary to use: terraform or tofu (default auto-detect)")
+		c.Flags().String("format", "text", "Output format: text or json")
+	}
+	terraformStateCmd.Flags().String("address", "", "Resource address to show from state")
+	terraformDriftCmd.Flags().String("profile", "", "AWS profile for the live resource comparison")
+	terraformDriftCmd.Flags().Bool("no-aws", false, "Skip comparing state against live AWS resources")
+	terraformDriftCmd.Flags().Int("max-lines", 80, "Maximum plan output lines to include in JSON output")
+	terraformDriftCmd.Flags().Bool("debug", false, "Enable debug output")
+	_ = terraformDriftCmd.RegisterFlagCompletionFunc("profile", completeAWSProfiles)
+}
+
+// awsLiveInventory lists the tagged resources in profile's account and
+// region. Untagged resources are not visible to the tagging API.
+func awsLiveInventory(profile string, debug bool) tfclient.LiveInventory {
+	return func(ctx context.Context) ([]tfclient.LiveResource, error) {
+		targetProfile := resolveAWSProfile(profile)
+		client, err := aws.NewClientWithProfileAndDebug(ctx, targetProfile, debug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AWS client with profile %s: %w", targetProfile, err)
+		}
+		output, err := client.ExecCLI(ctx, []string{"resourcegroupstaggingapi", "get-resources", "--output", "json"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tagged AWS resources: %w", err)
+		}
+		return tfclient.ParseTaggedResources([]byte(output))
+	}
+}
+
+// shouldRouteToTerraformStateAgent reports whether a question asks what
+// Terraform state holds or whether infrastructure has drifted
+func shouldRouteToTerraformStateAgent(question string) bool {
+	return tfclient.IsStateQuestion(question)
+}
+
+// handleTerraformStateQuery answers a state or drift question for the
+// workspace it names, or the default workspace
+func handleTerraformStateQuery(ctx context.Context, question string, debug bool, profile string) error {
+	req := tfclient.ParseStateRequest(question)
+	client, err := tfclient.NewClient(req.Workspace)
+	if err != nil {
+		return err
+	}
+	if debug {
+		fmt.Printf("Delegating query to Terraform state agent (workspace %s)...\n", client.Workspace())
+	}
+
+	if req.Op == tfclient.InspectState {
+		inv, err := client.Inventory(ctx, req.Address)
+		if err != nil {
+			return fmt.Errorf("terraform state agent error: %w", err)
+		}
+		fmt.Print(inv.Format())
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, terraformDriftTimeout)
+	defer cancel()
+	check, err := client.CheckDrift(ctx, awsLiveInventory(profile, debug), 0)
+	if err != nil {
+		return fmt.Errorf("terraform state agent error: %w", err)
+	}
+	fmt.Print(check.Format())
+	return nil
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/certs"
+	"github.com/bgdnvk/clanker/internal/cost"
+	ghclient "github.com/bgdnvk/clanker/internal/github"
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/bgdnvk/clanker/internal/notify"
+	"github.com/bgdnvk/clanker/internal/watch"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	watchConfigPath string
+	watchOnce       bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch --config checks.yaml",
+	Short: "Run scheduled checks and notify when their status changes",
+	Long: `Run the checks in a checks file on their intervals and notify when a
+check changes status. Check types:
+
+  cluster-health     SRE health of the cluster or one namespace
+  cert-expiry        certificates across ACM, Cloudflare, GCP and cert-manager
+  cost-anomaly       AWS cost anomalies above a percent threshold
+  failing-workflows  GitHub workflows whose latest run failed
+
+Each check is ok, warning, critical or error (the check could not run).
+The last status of every check is kept in ~/.clanker/watch/state.json, so
+notifications fire only on transitions, also across restarts. Notifications
+go to stdout, a webhook (the transition as JSON) or a Slack or Discord
+webhook, and to the notify sinks of the config file that take watch_alert.
+
+Example checks.yaml:
+
+  interval: 5m
+  notify:
+    - type: stdout
+    - type: slack
+      url: https://hooks.slack.com/services/...
+  checks:
+    - name: prod-cluster
+      type: cluster-health
+      context: prod
+    - name: certs
+      type: cert-expiry
+      interval: 6h
+      days: 21
+    - name: aws-cost
+      type: cost-anomaly
+      interval: 12h
+      threshold: 25
+    - name: api-ci
+      type: failing-workflows
+      repo: acme/api
+      branch: main
+
+Examples:
+  clanker watch --config checks.yaml
+  clanker watch --config checks.yaml --once`,
+	Args: cobra.NoArgs,
+	RunE: runWatch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringVar(&watchConfigPath, "config", "", "Checks file to run")
+	watchCmd.Flags().BoolVar(&watchOnce, "once", false, "Run every check once, print their statuses and exit")
+	_ = watchCmd.MarkFlagRequired("config")
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	debug := viper.GetBool("debug")
+	cfg, err := watch.LoadConfig(watchConfigPath)
+	if err != nil {
+		return err
+	}
+	statePath := cfg.StateFile
+	if statePath == "" {
+		if statePath, err = watch.DefaultStatePath(); err != nil {
+			return err
+		}
+	}
+	state, err := watch.LoadState(statePath)
+	if err != nil {
+		return err
+	}
+
+	notifiers := watch.NewNotifiers(cfg.Notify, os.Stdout)
+	if sinks := notify.FromConfig(); len(sinks.Sinks) > 0 {
+		notifiers = append(notifiers, &watch.Chat{Sender: sinks})
+	}
+	w := watch.New(cfg, watchCheckers(debug), notifiers, state, statePath, debug)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if watchOnce {
+		w.RunDue(ctx, time.Now())
+		for _, s := range w.Statuses() {
+			fmt.Printf("%-24s %-8s %s (since %s)\n", s.Name, s.Status, s.Summary, s.Since.Local().Format("2006-01-02 15:04"))
+		}
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Watching %d checks from %s (Ctrl+C to stop)\n", len(cfg.Checks), watchConfigPath)
+	return w.Run(ctx)
+}
+
+// watchCheckers builds each check type's clients when the check runs, so
+// a check whose provider is not configured fails on its own as an error
+// rather than stopping the others.
+func watchCheckers(debug bool) map[string]watch.Checker {
+	kubeconfig := viper.GetString("kubernetes.kubeconfig")
+	return map[string]watch.Checker{
+		watch.TypeClusterHealth: watch.ClusterHealth(func(kubeContext string) watch.HealthAPI {
+			return sre.NewHealthChecker(k8s.NewClient(kubeconfig, kubeContext, debug), debug)
+		}),
+		watch.TypeCertExpiry: watch.CertExpiry(func(ctx context.Context, profile string, sources []certs.Source) *certs.Report {
+			return certs.NewAgent(certsRunners(ctx, profile, debug), debug).List(ctx, sources)
+		}, time.Now),
+		watch.TypeCostAnomaly: watch.CostAnomaly(func(ctx context.Context, profile string) ([]cost.CostAnomaly, error) {
+			provider, err := cost.NewAWSProvider(ctx, resolveAWSProfile(profile), debug)
+			if err != nil {
+				return nil, err
+			}
+			return provider.GetAnomalies(ctx)
+		}),
+		watch.TypeFailingWorkflows: watch.FailingWorkflows(
+			ghclient.NewClient(viper.GetString("github.token"), viper.GetString("github.owner"), viper.GetString("github.repo")).ExecCLI),
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/bgdnvk/clanker/internal/agent"
 	awsclient "github.com/bgdnvk/clanker/internal/aws"
 	ghclient "github.com/bgdnvk/clanker/internal/github"
+	"github.com/bgdnvk/clanker/internal/redact"
 	"github.com/spf13/viper"
 	"google.golang.org/genai"
 )
@@ -384,11 +385,18 @@ type Message struct {
 	Content string `json:"content"`
 }
 
-// ConversationContext maintains state across multiple LLM turns for agentic workflows
+// ConversationContext maintains state across multiple LLM turns for agentic workflows.
+// Older turns are folded into Summary instead of being dropped (see Compact).
 type ConversationContext struct {
 	SystemPrompt string    `json:"system_prompt,omitempty"`
+	Summary      string    `json:"summary,omitempty"`
 	Messages     []Message `json:"messages"`
 	MaxHistory   int       `json:"-"`
+	MaxTokens    int       `json:"-"`
+
+	// unsummarized holds turns trimmed by MaxHistory that the next Compact
+	// has not folded into Summary yet
+	unsummarized []Message
 }
 
 // NewConversationContext creates a new conversation context with a system prompt
@@ -397,6 +405,7 @@ func NewConversationContext(systemPrompt string) *ConversationContext {
 		SystemPrompt: systemPrompt,
 		Messages:     make([]Message, 0),
 		MaxHistory:   20,
+		MaxTokens:    HistoryMaxTokens(),
 	}
 }
 
@@ -418,15 +427,15 @@ func (c *ConversationContext) AddAssistantMessage(content string) {
 	c.trimHistory()
 }
 
-// trimHistory keeps only the most recent messages
+// trimHistory keeps only the most recent messages. The trimmed ones are
+// queued for the next Compact so they end up in the summary.
 func (c *ConversationContext) trimHistory() {
-	if len(c.Messages) > c.MaxHistory {
-		// Keep first message (usually important context) and last N-1 messages
-		c.Messages = append(
-			c.Messages[:1],
-			c.Messages[len(c.Messages)-(c.MaxHistory-1):]...,
-		)
+	if c.MaxHistory <= 0 || len(c.Messages) <= c.MaxHistory {
+		return
 	}
+	split := startOnUserTurn(c.Messages, len(c.Messages)-c.MaxHistory)
+	c.unsummarized = append(c.unsummarized, c.Messages[:split]...)
+	c.Messages = append([]Message(nil), c.Messages[split:]...)
 }
 
 // GetMessages returns all messages for API call
@@ -1011,6 +1020,8 @@ func (c *Client) buildPrompt(question, awsContext, codeContext, githubContext st
 }
 
 func (c *Client) askBedrock(ctx context.Context, prompt string) (string, error) {
+	prompt = redact.String(prompt)
+
 	// NOTE: Using AWS CLI approach due to SSO credential caching issues with Go SDK
 	// Previous working AWS SDK approach (commented out for reference):
 	// cfg, err := config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile("commercial-dev"))
@@ -1106,6 +1117,8 @@ func (c *Client) askBedrock(ctx context.Context, prompt string) (string, error)
 
 // executeAWSTool executes a specific AWS tool with the given parameters
 func (c *Client) askGemini(ctx context.Context, prompt string) (string, error) {
+	prompt = redact.String(prompt)
+
 	if c.geminiClient == nil {
 		return "", fmt.Errorf("gemini client not initialized")
 	}
@@ -1201,6 +1214,8 @@ func (c *Client) resolveLocalModelInferenceURL(profile *awsclient.AIProfile) str
 }
 
 func (c *Client) askOpenAI(ctx context.Context, prompt string) (string, error) {
+	prompt = redact.String(prompt)
+
 	// Get the AI profile configuration (this is the profileLLMCall for OpenAI API access)
 	profileLLMCall, err := c.getAIProfile(c.aiProfile)
 	if err != nil {
@@ -1328,6 +1343,7 @@ func (c *Client) askClankerCloud(ctx context.Context, prompt string) (string, er
 }
 
 func (c *Client) askClankerCloudMessages(ctx context.Context, messages []Message) (string, error) {
+	messages = redactMessages(messages)
 	token := resolveClankerCloudLLMToken(c.apiKey)
 	if strings.TrimSpace(token) == "" {
 		return "", fmt.Errorf("Clanker Cloud auth token not configured")
@@ -1507,6 +1523,8 @@ func (c *Client) resolveGitHubModelsToken(ctx context.Context) string {
 }
 
 func (c *Client) askGitHubModels(ctx context.Context, prompt string) (string, error) {
+	prompt = redact.String(prompt)
+
 	profileLLMCall, err := c.getAIProfile(c.aiProfile)
 	if err != nil {
 		return "", fmt.Errorf("failed to get AI profile for LLM calls: %w", err)
@@ -1597,6 +1615,8 @@ func (c *Client) askGitHubModels(ctx context.Context, prompt string) (string, er
 }
 
 func (c *Client) askCohere(ctx context.Context, prompt string) (string, error) {
+	prompt = redact.String(prompt)
+
 	profileLLMCall, err := c.getAIProfile(c.aiProfile)
 	if err != nil {
 		return "", fmt.Errorf("failed to get AI profile for LLM calls: %w", err)
@@ -1686,6 +1706,8 @@ func (c *Client) askCohere(ctx context.Context, prompt string) (string, error) {
 }
 
 func (c *Client) askAnthropic(ctx context.Context, prompt string) (string, error) {
+	prompt = redact.String(prompt)
+
 	profileLLMCall, err := c.getAIProfile(c.aiProfile)
 	if err != nil {
 		return "", fmt.Errorf("failed to get AI profile for LLM calls: %w", err)
@@ -1813,6 +1835,8 @@ func (c *Client) askAnthropic(ctx context.Context, prompt string) (string, error
 
 // askMiniMax sends a prompt to MiniMax using the Anthropic-compatible API.
 func (c *Client) askMiniMax(ctx context.Context, prompt string) (string, error) {
+	prompt = redact.String(prompt)
+
 	profileLLMCall, err := c.getAIProfile(c.aiProfile)
 	if err != nil {
 		return "", fmt.Errorf("failed to get AI profile for LLM calls: %w", err)
@@ -2137,8 +2161,15 @@ func (c *Client) AskPrompt(ctx context.Context, prompt string) (string, error) {
 // AskWithContext sends a prompt maintaining conversation context for multi-turn interactions.
 // This is used by agentic workflows that need to maintain state across multiple LLM calls.
 func (c *Client) AskWithContext(ctx context.Context, conv *ConversationContext, prompt string) (string, error) {
-	// Add user message to history
-	conv.AddUserMessage(prompt)
+	// Scrub credentials before they reach the provider or the history
+	conv.SystemPrompt = redact.String(conv.SystemPrompt)
+	conv.AddUserMessage(redact.String(prompt))
+
+	// Fold older turns into the rolling summary once the history outgrows
+	// ai.history.max_tokens
+	if err := conv.Compact(ctx, c.AskPrompt); err != nil && c.debug {
+		fmt.Printf("[ai] history summarization failed, kept an extractive summary: %v\n", err)
+	}
 
 	var response string
 	var err error
@@ -2176,8 +2207,8 @@ func (c *Client) AskWithContext(ctx context.Context, conv *ConversationContext,
 
 func (c *Client) askClankerCloudWithHistory(ctx context.Context, conv *ConversationContext) (string, error) {
 	messages := make([]Message, 0, len(conv.Messages)+1)
-	if conv.SystemPrompt != "" {
-		messages = append(messages, Message{Role: "system", Content: conv.SystemPrompt})
+	if conv.System() != "" {
+		messages = append(messages, Message{Role: "system", Content: conv.System()})
 	}
 	messages = append(messages, conv.Messages...)
 	return c.askClankerCloudMessages(ctx, messages)
@@ -2194,10 +2225,10 @@ func (c *Client) askBedrockWithHistory(ctx context.Context, conv *ConversationCo
 	messages := make([]Message, 0, len(conv.Messages)+1)
 
 	// Add system prompt as first user message if present
-	if conv.SystemPrompt != "" {
+	if conv.System() != "" {
 		messages = append(messages, Message{
 			Role:    "user",
-			Content: sanitizeASCII(conv.SystemPrompt),
+			Content: sanitizeASCII(conv.System()),
 		})
 		messages = append(messages, Message{
 			Role:    "assistant",
@@ -2305,10 +2336,10 @@ func (c *Client) askAnthropicWithHistory(ctx context.Context, conv *Conversation
 	messages := make([]anthropicMessage, 0, len(conv.Messages)+1)
 
 	// Add system prompt as first user message if present
-	if conv.SystemPrompt != "" {
+	if conv.System() != "" {
 		messages = append(messages, anthropicMessage{
 			Role:    "user",
-			Content: []map[string]any{{"type": "text", "text": sanitizeASCII(conv.SystemPrompt)}},
+			Content: []map[string]any{{"type": "text", "text": sanitizeASCII(conv.System())}},
 		})
 		messages = append(messages, anthropicMessage{
 			Role:    "assistant",
@@ -2414,10 +2445,10 @@ func (c *Client) askMiniMaxWithHistory(ctx context.Context, conv *ConversationCo
 
 	messages := make([]anthropicMessage, 0, len(conv.Messages)+1)
 
-	if conv.SystemPrompt != "" {
+	if conv.System() != "" {
 		messages = append(messages, anthropicMessage{
 			Role:    "user",
-			Content: []map[string]any{{"type": "text", "text": sanitizeASCII(conv.SystemPrompt)}},
+			Content: []map[string]any{{"type": "text", "text": sanitizeASCII(conv.System())}},
 		})
 		messages = append(messages, anthropicMessage{
 			Role:    "assistant",
@@ -2523,10 +2554,10 @@ func (c *Client) askOpenAIWithHistory(ctx context.Context, conv *ConversationCon
 	messages := make([]Message, 0, len(conv.Messages)+1)
 
 	// Add system prompt if present
-	if conv.SystemPrompt != "" {
+	if conv.System() != "" {
 		messages = append(messages, Message{
 			Role:    "system",
-			Content: conv.SystemPrompt,
+			Content: conv.System(),
 		})
 	}
 
@@ -2626,8 +2657,8 @@ func (c *Client) askOpenAIWithHistory(ctx context.Context, conv *ConversationCon
 
 func (c *Client) askGitHubModelsWithHistory(ctx context.Context, conv *ConversationContext) (string, error) {
 	messages := make([]Message, 0, len(conv.Messages)+1)
-	if conv.SystemPrompt != "" {
-		messages = append(messages, Message{Role: "system", Content: sanitizeASCII(conv.SystemPrompt)})
+	if conv.System() != "" {
+		messages = append(messages, Message{Role: "system", Content: sanitizeASCII(conv.System())})
 	}
 	messages = append(messages, conv.Messages...)
 
@@ -2719,9 +2750,9 @@ func (c *Client) askGeminiWithHistory(ctx context.Context, conv *ConversationCon
 
 	// Build combined prompt from conversation history
 	var promptBuilder strings.Builder
-	if conv.SystemPrompt != "" {
+	if conv.System() != "" {
 		promptBuilder.WriteString("System: ")
-		promptBuilder.WriteString(conv.SystemPrompt)
+		promptBuilder.WriteString(conv.System())
 		promptBuilder.WriteString("\n\n")
 	}
 
@@ -2799,10 +2830,10 @@ func (c *Client) askCohereWithHistory(ctx context.Context, conv *ConversationCon
 	emitProgressTrace("provider", fmt.Sprintf("Calling Cohere with model %s.", model))
 
 	messages := make([]cohereChatMessage, 0, len(conv.Messages)+1)
-	if conv.SystemPrompt != "" {
+	if conv.System() != "" {
 		messages = append(messages, cohereChatMessage{
 			Role:    "system",
-			Content: sanitizeASCII(conv.SystemPrompt),
+			Content: sanitizeASCII(conv.System()),
 		})
 	}
 
@@ -3270,6 +3301,15 @@ func sampleChunks(chunks []string, k int) []string {
 	return sampled
 }
 
+// redactMessages returns messages with credentials scrubbed from their content
+func redactMessages(messages []Message) []Message {
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		out[i] = Message{Role: m.Role, Content: redact.String(m.Content)}
+	}
+	return out
+}
+
 // sanitizeASCII strips non-ASCII runes to avoid CLI argv issues and provider limits
 func sanitizeASCII(s string) string {
 	// Fast path: if all bytes < 128
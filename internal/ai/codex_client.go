@@ -11,6 +11,8 @@ import (
 	"os"
 	"strings"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/redact"
 )
 
 var codexResponsesURL = "https://chatgpt.com/backend-api/codex/responses"
@@ -152,6 +154,8 @@ type codexStreamEvent struct {
 func doCodexStreamingRequest(ctx context.Context, oauthToken string, codexReq CodexRequest) (*http.Response, error) {
 	codexReq.Store = false
 	codexReq.Stream = true
+	codexReq.Instructions = redact.String(codexReq.Instructions)
+	codexReq.Input = redactMessages(codexReq.Input)
 	if codexReq.Input == nil {
 		codexReq.Input = []Message{}
 	}
@@ -293,7 +297,7 @@ func (c *Client) askCodexWithHistory(ctx context.Context, conv *ConversationCont
 	for i, m := range conv.Messages {
 		input[i] = Message{Role: m.Role, Content: sanitizeASCII(m.Content)}
 	}
-	return askCodexStreaming(ctx, oauthToken, model, codexInstructions(conv.SystemPrompt), input)
+	return askCodexStreaming(ctx, oauthToken, model, codexInstructions(conv.System()), input)
 }
 
 // IsOpenAIOAuthActive returns true if the user has a saved OAuth token
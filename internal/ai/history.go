@@ -0,0 +1,191 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultHistoryMaxTokens is the history budget used when
+// ai.history.max_tokens is not set. It leaves room for the system prompt
+// and the answer in the smallest context windows clanker supports.
+const DefaultHistoryMaxTokens = 24000
+
+const (
+	// summaryInputChars caps how much of each turn is shown to the summarizer
+	summaryInputChars = 2000
+	// extractiveLineChars caps each line of the fallback summary
+	extractiveLineChars = 200
+)
+
+// HistoryMaxTokens returns ai.history.max_tokens, or DefaultHistoryMaxTokens
+func HistoryMaxTokens() int {
+	if n := viper.GetInt("ai.history.max_tokens"); n > 0 {
+		return n
+	}
+	return DefaultHistoryMaxTokens
+}
+
+// EstimateTokens approximates the token count of s at four bytes per token.
+// It is deliberately provider-independent so compaction decisions are the
+// same whichever model answers.
+func EstimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// System returns the system prompt with the rolling summary of earlier
+// turns appended
+func (c *ConversationContext) System() string {
+	if c.Summary == "" {
+		return c.SystemPrompt
+	}
+	summary := "Summary of the earlier conversation:\n" + c.Summary
+	if c.SystemPrompt == "" {
+		return summary
+	}
+	return c.SystemPrompt + "\n\n" + summary
+}
+
+// Tokens estimates the size of everything sent for the next turn
+func (c *ConversationContext) Tokens() int {
+	total := EstimateTokens(c.System())
+	for _, m := range c.Messages {
+		total += EstimateTokens(m.Content)
+	}
+	return total
+}
+
+// Compact keeps the history within MaxTokens. When it is over budget, or
+// MaxHistory trimmed turns are waiting, the oldest turns are summarized with
+// summarize together with the previous summary, and the newest turns that
+// fit in half the budget are kept verbatim. The summary is capped at a
+// quarter of the budget. The split depends only on the message sizes, so
+// the same history always compacts the same way.
+//
+// If summarize fails or returns nothing, an extractive summary (the first
+// line of each turn) is used instead and the error is returned so callers
+// can report it; the history is compacted either way.
+func (c *ConversationContext) Compact(ctx context.Context, summarize func(context.Context, string) (string, error)) error {
+	if c.MaxTokens <= 0 {
+		return nil
+	}
+	if len(c.unsummarized) == 0 && c.Tokens() <= c.MaxTokens {
+		return nil
+	}
+
+	split := recentSplit(c.Messages, c.MaxTokens/2)
+	older := append(append([]Message(nil), c.unsummarized...), c.Messages[:split]...)
+	if len(older) == 0 {
+		return nil
+	}
+
+	var err error
+	summary := ""
+	if summarize != nil {
+		summary, err = summarize(ctx, summaryPrompt(c.Summary, older))
+		summary = strings.TrimSpace(summary)
+		if err == nil && summary == "" {
+			err = fmt.Errorf("summarizer returned an empty summary")
+		}
+	}
+	if summary == "" || err != nil {
+		summary = extractiveSummary(c.Summary, older)
+	}
+
+	c.Summary = capChars(summary, c.MaxTokens/4)
+	c.Messages = append([]Message(nil), c.Messages[split:]...)
+	c.unsummarized = nil
+	return err
+}
+
+// recentSplit returns the index of the first message kept verbatim: the
+// newest messages that fit in budget tokens, always at least the last one,
+// starting on a user turn so roles still alternate after the cut.
+func recentSplit(messages []Message, budget int) int {
+	if len(messages) == 0 {
+		return 0
+	}
+	split := len(messages) - 1
+	used := EstimateTokens(messages[split].Content)
+	for split > 0 {
+		next := EstimateTokens(messages[split-1].Content)
+		if used+next > budget {
+			break
+		}
+		used += next
+		split--
+	}
+	return startOnUserTurn(messages, split)
+}
+
+// startOnUserTurn moves split forward past assistant messages, but never
+// past the last message
+func startOnUserTurn(messages []Message, split int) int {
+	for split < len(messages)-1 && messages[split].Role != "user" {
+		split++
+	}
+	return split
+}
+
+func summaryPrompt(previous string, turns []Message) string {
+	var sb strings.Builder
+	sb.WriteString("Summarize this conversation between a user and an infrastructure assistant so it can continue without the full transcript.\n")
+	sb.WriteString("Keep resource names, IDs, commands that were run, their results, errors, and decisions made. Drop pleasantries and repetition.\n")
+	sb.WriteString("Respond with the summary only, as short bullet points.\n\n")
+	if previous != "" {
+		sb.WriteString("Summary so far:\n")
+		sb.WriteString(previous)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("Turns to add:\n")
+	for _, m := range turns {
+		content := m.Content
+		if len(content) > summaryInputChars {
+			content = content[:summaryInputChars] + "..."
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", roleLabel(m.Role), content)
+	}
+	return sb.String()
+}
+
+// extractiveSummary is the fallback when the summarizer is unavailable: the
+// previous summary followed by the first line of each turn
+func extractiveSummary(previous string, turns []Message) string {
+	var lines []string
+	if previous != "" {
+		lines = append(lines, previous)
+	}
+	for _, m := range turns {
+		line := strings.TrimSpace(m.Content)
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+		if len(line) > extractiveLineChars {
+			line = line[:extractiveLineChars] + "..."
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %s", roleLabel(m.Role), line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// capChars keeps the last maxTokens worth of s, so the newest part of a
+// growing summary survives
+func capChars(s string, maxTokens int) string {
+	limit := maxTokens * 4
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	return "..." + s[len(s)-limit:]
+}
+
+func roleLabel(role string) string {
+	if role == "assistant" {
+		return "Assistant"
+	}
+	return "User"
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func turn(role string, tokens int, label string) Message {
+	content := label + strings.Repeat(".", tokens*4-len(label))
+	return Message{Role: role, Content: content}
+}
+
+func TestCompactUnderBudgetIsNoop(t *testing.T) {
+	conv := &ConversationContext{MaxTokens: 1000, MaxHistory: 20}
+	conv.AddUserMessage("hello")
+	conv.AddAssistantMessage("hi")
+
+	called := false
+	err := conv.Compact(context.Background(), func(context.Context, string) (string, error) {
+		called = true
+		return "", nil
+	})
+	if err != nil || called || len(conv.Messages) != 2 || conv.Summary != "" {
+		t.Fatalf("under-budget history should be left alone: called=%v err=%v conv=%+v", called, err, conv)
+	}
+}
+
+func TestCompactSummarizesOldestTurns(t *testing.T) {
+	conv := &ConversationContext{SystemPrompt: "You are an agent.", MaxTokens: 100, MaxHistory: 20}
+	conv.Messages = []Message{
+		turn("user", 30, "u1"),
+		turn("assistant", 30, "a1"),
+		turn("user", 20, "u2"),
+		turn("assistant", 20, "a2"),
+		turn("user", 20, "u3"),
+	}
+
+	var prompt string
+	err := conv.Compact(context.Background(), func(_ context.Context, p string) (string, error) {
+		prompt = p
+		return "- user asked about u1 and u2", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Half the budget (50 tokens) holds a2 + u3; a2 is an assistant turn, so
+	// the cut moves forward to keep roles alternating from a user turn.
+	if len(conv.Messages) != 1 || !strings.HasPrefix(conv.Messages[0].Content, "u3") {
+		t.Fatalf("kept messages = %+v, want only u3", conv.Messages)
+	}
+	for _, label := range []string{"u1", "a1", "u2", "a2"} {
+		if !strings.Contains(prompt, label) {
+			t.Errorf("summary prompt missing %s", label)
+		}
+	}
+	if strings.Contains(prompt, "u3") {
+		t.Error("the kept turn should not be summarized")
+	}
+	if !strings.Contains(conv.System(), "You are an agent.") || !strings.Contains(conv.System(), "- user asked about u1 and u2") {
+		t.Errorf("System() = %q, want prompt plus summary", conv.System())
+	}
+}
+
+func TestCompactIsDeterministic(t *testing.T) {
+	build := func() *ConversationContext {
+		conv := &ConversationContext{MaxTokens: 60, MaxHistory: 20}
+		for i := 0; i < 6; i++ {
+			conv.AddUserMessage(turn("user", 15, "q").Content)
+			conv.AddAssistantMessage(turn("assistant", 15, "a").Content)
+		}
+		return conv
+	}
+	first, second := build(), build()
+	echo := func(_ context.Context, p string) (string, error) { return "summary of " + p[len(p)-20:], nil }
+	if err := first.Compact(context.Background(), echo); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Compact(context.Background(), echo); err != nil {
+		t.Fatal(err)
+	}
+	if first.Summary != second.Summary || len(first.Messages) != len(second.Messages) {
+		t.Fatalf("same history compacted differently: %+v vs %+v", first, second)
+	}
+	if first.Tokens() > first.MaxTokens {
+		t.Errorf("compacted history is %d tokens, budget %d", first.Tokens(), first.MaxTokens)
+	}
+}
+
+func TestCompactFallsBackToExtractiveSummary(t *testing.T) {
+	conv := &ConversationContext{MaxTokens: 100, MaxHistory: 20}
+	conv.Messages = []Message{
+		{Role: "user", Content: "list buckets\n" + strings.Repeat("x", 200)},
+		{Role: "assistant", Content: "found 3 buckets\n" + strings.Repeat("y", 200)},
+		{Role: "user", Content: "which are public?"},
+	}
+
+	err := conv.Compact(context.Background(), func(context.Context, string) (string, error) {
+		return "", errors.New("provider down")
+	})
+	if err == nil {
+		t.Fatal("Compact should report the summarizer error")
+	}
+	want := "- User: list buckets\n- Assistant: found 3 buckets"
+	if conv.Summary != want {
+		t.Errorf("Summary = %q, want %q", conv.Summary, want)
+	}
+	if len(conv.Messages) != 1 || conv.Messages[0].Content != "which are public?" {
+		t.Errorf("kept messages = %+v", conv.Messages)
+	}
+}
+
+func TestTrimmedTurnsAreSummarized(t *testing.T) {
+	conv := &ConversationContext{MaxTokens: 10000, MaxHistory: 4}
+	for _, label := range []string{"q1", "a1", "q2", "a2", "q3", "a3"} {
+		if strings.HasPrefix(label, "q") {
+			conv.AddUserMessage(label)
+		} else {
+			conv.AddAssistantMessage(label)
+		}
+	}
+	if len(conv.Messages) != 4 || conv.Messages[0].Content != "q2" {
+		t.Fatalf("MaxHistory trim kept %+v", conv.Messages)
+	}
+
+	var prompt string
+	if err := conv.Compact(context.Background(), func(_ context.Context, p string) (string, error) {
+		prompt = p
+		return "- q1 answered", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "User: q1") || !strings.Contains(prompt, "Assistant: a1") {
+		t.Errorf("trimmed turns missing from summary prompt:\n%s", prompt)
+	}
+	if conv.Summary != "- q1 answered" || len(conv.Messages) != 4 {
+		t.Errorf("after compaction Summary=%q messages=%d", conv.Summary, len(conv.Messages))
+	}
+}
+
+func TestHistoryMaxTokensDefault(t *testing.T) {
+	if got := HistoryMaxTokens(); got != DefaultHistoryMaxTokens {
+		t.Errorf("HistoryMaxTokens() = %d, want %d", got, DefaultHistoryMaxTokens)
+	}
+}
@@ -18,7 +18,7 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", s.cfg.CORSOrigin)
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-API-Key")
 		w.Header().Set("Access-Control-Max-Age", "600")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
@@ -39,8 +39,13 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
+		// Backends that hold the token as an API key send it in
+		// X-API-Key instead of a bearer header
 		auth := r.Header.Get("Authorization")
 		const prefix = "Bearer "
+		if key := r.Header.Get("X-API-Key"); key != "" && auth == "" {
+			auth = prefix + key
+		}
 		if !strings.HasPrefix(auth, prefix) {
 			s.log401(r, "missing or non-bearer Authorization header")
 			writeError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid bearer token")
@@ -93,3 +98,9 @@ func (r *statusRecorder) WriteHeader(code int) {
 	r.status = code
 	r.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap lets http.ResponseController reach the connection to flush
+// streamed responses and lift the write deadline
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
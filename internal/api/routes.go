@@ -87,6 +87,13 @@ func (s *Server) registerRoutes() {
 
 	// Code view
 	s.mux.HandleFunc("POST /api/v1/code/analyze", s.handleCodeAnalyze)
+
+	// Ask pipeline — the same commands the CLI runs, so the hosted
+	// frontend no longer shells out per request.
+	s.mux.HandleFunc("POST /api/v1/ask", s.handleAsk)
+	s.mux.HandleFunc("POST /api/v1/plans", s.handleCreatePlan)
+	s.mux.HandleFunc("POST /api/v1/plans/{id}/apply", s.handleApplyPlan)
+	s.mux.HandleFunc("GET /api/v1/route", s.handleRoute)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
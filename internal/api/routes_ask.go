@@ -0,0 +1,430 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The ask, plan, apply and route endpoints run the clanker binary itself,
+// the same way the MCP server's clanker_run tool does. Each request gets
+// its own process, so the CLI's package-level flag and viper state never
+// leaks between concurrent requests, and output streams line by line as
+// the CLI prints it.
+
+// askProviders are the `clanker ask` context flags a request may name.
+// Anything else is rejected so a request cannot smuggle arbitrary flags
+// into the command line.
+var askProviders = map[string]bool{
+	"aws": true, "gcp": true, "azure": true, "cloudflare": true, "digitalocean": true,
+	"hetzner": true, "oracle": true, "vercel": true, "flyio": true, "railway": true,
+	"verda": true, "tencent": true, "github": true, "gitlab": true, "terraform": true,
+	"pulumi": true, "cicd": true, "db": true, "iam": true, "observability": true, "sre": true,
+}
+
+// planIDRegex matches stored plan IDs such as 20261015-120000-a1b2, and
+// the prefixes `clanker plan` accepts
+var planIDRegex = regexp.MustCompile(`^[0-9A-Za-z-]{4,64}$`)
+
+// savedPlanRegex finds the ID `clanker ask --maker` prints to stderr once
+// it stores a plan
+var savedPlanRegex = regexp.MustCompile(`Saved as plan (\S+)`)
+
+// maxCLIOutput caps the stdout and stderr kept per request; streamed
+// lines are still sent in full
+const maxCLIOutput = 4 << 20
+
+// askRequest is the JSON body of POST /api/v1/ask and POST /api/v1/plans
+type askRequest struct {
+	Question string `json:"question"`
+	// Provider adds that provider's context, like --aws
+	Provider string `json:"provider"`
+	// Agent pins the agent, like --agent
+	Agent   string `json:"agent"`
+	Profile string `json:"profile"`
+	// Destroyer allows destructive operations in a generated plan
+	Destroyer bool `json:"destroyer"`
+	// Stream sends output as NDJSON events while the command runs
+	Stream bool `json:"stream"`
+}
+
+// applyPlanRequest is the optional JSON body of POST
+// /api/v1/plans/{id}/apply
+type applyPlanRequest struct {
+	Profile string `json:"profile"`
+	Stream  bool   `json:"stream"`
+}
+
+// streamEvent is one NDJSON line of a streamed response. Output lines
+// carry Stream and Text; the last event has Done set and the same data or
+// error the non-streamed response would have returned.
+type streamEvent struct {
+	Stream   string      `json:"stream,omitempty"` // stdout or stderr
+	Text     string      `json:"text,omitempty"`
+	Done     bool        `json:"done,omitempty"`
+	ExitCode *int        `json:"exit_code,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+	Error    *apiError   `json:"error,omitempty"`
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string { return e.Message }
+
+// cliResult is how a clanker run ended
+type cliResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Duration time.Duration
+}
+
+// finishFunc turns a finished run into the response data, or an error
+// that is returned as a 502 with its code
+type finishFunc func(res cliResult) (interface{}, *apiError)
+
+func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	var req askRequest
+	if !decodeBody(w, r, 64<<10, &req) {
+		return
+	}
+	args, apiErr := askArgs(req)
+	if apiErr != nil {
+		writeError(w, http.StatusBadRequest, apiErr.Code, apiErr.Message)
+		return
+	}
+	s.serveCLI(r.Context(), w, req.Stream, args, func(res cliResult) (interface{}, *apiError) {
+		if res.ExitCode != 0 {
+			return nil, cliFailure("ask_failed", res)
+		}
+		return map[string]interface{}{
+			"answer":   res.Stdout,
+			"duration": res.Duration.Round(time.Millisecond).String(),
+		}, nil
+	})
+}
+
+// handleCreatePlan generates a plan with `clanker ask --maker`, which also
+// stores it, and returns the plan with its stored ID for
+// POST /api/v1/plans/{id}/apply
+func (s *Server) handleCreatePlan(w http.ResponseWriter, r *http.Request) {
+	var req askRequest
+	if !decodeBody(w, r, 64<<10, &req) {
+		return
+	}
+	args, apiErr := askArgs(req)
+	if apiErr != nil {
+		writeError(w, http.StatusBadRequest, apiErr.Code, apiErr.Message)
+		return
+	}
+	flags := []string{"--maker"}
+	if req.Destroyer {
+		flags = append(flags, "--destroyer")
+	}
+	args = append(args[:1], append(flags, args[1:]...)...)
+
+	s.serveCLI(r.Context(), w, req.Stream, args, func(res cliResult) (interface{}, *apiError) {
+		if res.ExitCode != 0 {
+			return nil, cliFailure("plan_failed", res)
+		}
+		plan := strings.TrimSpace(res.Stdout)
+		if !json.Valid([]byte(plan)) {
+			return nil, &apiError{Code: "unparseable_plan", Message: "clanker did not print a JSON plan: " + tail(plan, 500)}
+		}
+		data := map[string]interface{}{
+			"plan":     json.RawMessage(plan),
+			"duration": res.Duration.Round(time.Millisecond).String(),
+		}
+		if m := savedPlanRegex.FindStringSubmatch(res.Stderr); m != nil {
+			data["id"] = m[1]
+		}
+		return data, nil
+	})
+}
+
+// handleApplyPlan applies a stored plan with `clanker ask --apply
+// --plan-id`. The apply keeps running if the client disconnects, and a
+// plan that is already being applied is refused.
+func (s *Server) handleApplyPlan(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSpace(r.PathValue("id"))
+	if !planIDRegex.MatchString(id) {
+		writeError(w, http.StatusBadRequest, "invalid_plan_id", "plan id must be a stored plan ID such as 20261015-120000-a1b2")
+		return
+	}
+	var req applyPlanRequest
+	if r.ContentLength != 0 && !decodeBody(w, r, 16<<10, &req) {
+		return
+	}
+	args := []string{"ask", "--apply", "--plan-id", id}
+	if profile := strings.TrimSpace(req.Profile); profile != "" {
+		args = append(args, "--profile", profile)
+	}
+
+	if !s.startApply(id) {
+		writeError(w, http.StatusConflict, "apply_in_progress", "plan "+id+" is already being applied")
+		return
+	}
+	defer s.finishApply(id)
+
+	// Stopping an apply halfway leaves resources half created, so the
+	// process outlives the request
+	ctx := context.WithoutCancel(r.Context())
+	start := time.Now()
+	s.serveCLI(ctx, w, req.Stream, args, func(res cliResult) (interface{}, *apiError) {
+		rec := ApplyRecord{
+			StartedAt: start,
+			Provider:  "plan",
+			Status:    "ok",
+			Duration:  res.Duration.Round(time.Millisecond).String(),
+			Summary:   "plan " + id,
+			Output:    res.Stdout,
+		}
+		resp := applyResponse{Provider: "plan", Status: "ok", Output: res.Stdout, Duration: rec.Duration}
+		if res.ExitCode != 0 {
+			rec.Status, resp.Status = "error", "error"
+			rec.Error = tail(res.Stderr, 2000)
+			resp.Error = rec.Error
+		}
+		if s.history != nil {
+			rec = s.history.append(rec)
+			resp.HistoryID = rec.ID
+		}
+		return resp, nil
+	})
+}
+
+// handleRoute returns the routing decision of `clanker ask --route-only`
+// for ?question=
+func (s *Server) handleRoute(w http.ResponseWriter, r *http.Request) {
+	question := strings.TrimSpace(r.URL.Query().Get("question"))
+	if question == "" {
+		writeError(w, http.StatusBadRequest, "missing_question", "question is required")
+		return
+	}
+	s.serveCLI(r.Context(), w, false, []string{"ask", "--route-only", "--", question}, func(res cliResult) (interface{}, *apiError) {
+		if res.ExitCode != 0 {
+			return nil, cliFailure("route_failed", res)
+		}
+		decision := strings.TrimSpace(res.Stdout)
+		if !json.Valid([]byte(decision)) {
+			return nil, &apiError{Code: "invalid_json", Message: "clanker did not print a routing decision"}
+		}
+		return json.RawMessage(decision), nil
+	})
+}
+
+// askArgs builds `clanker ask [--provider] [--agent x] [--profile x] --
+// question`. The question goes after -- so one starting with a dash is
+// never read as a flag.
+func askArgs(req askRequest) ([]string, *apiError) {
+	question := strings.TrimSpace(req.Question)
+	if question == "" {
+		return nil, &apiError{Code: "missing_question", Message: "question is required"}
+	}
+	args := []string{"ask"}
+	if provider := strings.ToLower(strings.TrimSpace(req.Provider)); provider != "" {
+		if !askProviders[provider] {
+			return nil, &apiError{Code: "unsupported_provider", Message: fmt.Sprintf("unknown provider %q", req.Provider)}
+		}
+		args = append(args, "--"+provider)
+	}
+	if agent := strings.TrimSpace(req.Agent); agent != "" {
+		args = append(args, "--agent", agent)
+	}
+	if profile := strings.TrimSpace(req.Profile); profile != "" {
+		args = append(args, "--profile", profile)
+	}
+	return append(args, "--", question), nil
+}
+
+// serveCLI runs clanker with args in one of the server's slots and
+// responds with finish's data: as a JSON envelope, or as NDJSON output
+// events ending in a done event when stream is set. A request that finds
+// every slot busy gets a 429 rather than queueing behind long asks.
+func (s *Server) serveCLI(ctx context.Context, w http.ResponseWriter, stream bool, args []string, finish finishFunc) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	default:
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusTooManyRequests, "busy", fmt.Sprintf("all %d slots are busy; retry shortly", cap(s.slots)))
+		return
+	}
+
+	// Asks and applies routinely outlast the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	if !stream {
+		res, err := s.runCLI(ctx, args, nil)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "exec_failed", err.Error())
+			return
+		}
+		data, apiErr := finish(res)
+		if apiErr != nil {
+			writeError(w, http.StatusBadGateway, apiErr.Code, apiErr.Message)
+			return
+		}
+		writeData(w, data)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	var mu sync.Mutex
+	send := func(ev streamEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := enc.Encode(ev); err == nil {
+			_ = rc.Flush()
+		}
+	}
+
+	res, err := s.runCLI(ctx, args, func(stream, line string) {
+		send(streamEvent{Stream: stream, Text: line})
+	})
+	if err != nil {
+		send(streamEvent{Done: true, Error: &apiError{Code: "exec_failed", Message: err.Error()}})
+		return
+	}
+	done := streamEvent{Done: true, ExitCode: &res.ExitCode}
+	done.Data, done.Error = finish(res)
+	send(done)
+}
+
+// runCLI runs the clanker executable with args. When emit is set, each
+// line of stdout and stderr is passed to it as soon as it is written.
+// A non-zero exit is reported in the result, not as an error.
+func (s *Server) runCLI(ctx context.Context, args []string, emit func(stream, line string)) (cliResult, error) {
+	exe := s.cfg.Executable
+	if exe == "" {
+		var err error
+		if exe, err = os.Executable(); err != nil {
+			return cliResult{}, fmt.Errorf("resolve clanker executable: %w", err)
+		}
+	}
+	if len(s.cfg.Flags) > 0 {
+		args = append(append(args[:1:1], s.cfg.Flags...), args[1:]...)
+	}
+	cmd := exec.CommandContext(ctx, exe, args...)
+	cmd.Env = append(os.Environ(), s.cfg.Env...)
+	cmd.WaitDelay = time.Second
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return cliResult{}, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return cliResult{}, err
+	}
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return cliResult{}, fmt.Errorf("start clanker: %w", err)
+	}
+	if s.cfg.Debug {
+		s.logger.Printf("[api] running clanker %s", strings.Join(args[:min(len(args), 2)], " "))
+	}
+
+	var outBuf, errBuf bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); collect(stdout, &outBuf, "stdout", emit) }()
+	go func() { defer wg.Done(); collect(stderr, &errBuf, "stderr", emit) }()
+	wg.Wait()
+
+	res := cliResult{Stdout: outBuf.String(), Stderr: errBuf.String(), Duration: time.Since(start)}
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return res, fmt.Errorf("clanker did not finish: %w", err)
+		}
+		res.ExitCode = exitErr.ExitCode()
+	}
+	return res, nil
+}
+
+// collect copies r into buf, up to maxCLIOutput, emitting each line
+func collect(r io.Reader, buf *bytes.Buffer, stream string, emit func(stream, line string)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if buf.Len() < maxCLIOutput {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+		if emit != nil {
+			emit(stream, line)
+		}
+	}
+	// Drain anything left after an overlong line so the process never
+	// blocks on a full pipe
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// startApply claims id for one apply at a time
+func (s *Server) startApply(id string) bool {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	if s.applying[id] {
+		return false
+	}
+	s.applying[id] = true
+	return true
+}
+
+func (s *Server) finishApply(id string) {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	delete(s.applying, id)
+}
+
+// decodeBody reads a JSON body of at most limit bytes into v, writing the
+// error response itself when it cannot
+func decodeBody(w http.ResponseWriter, r *http.Request, limit int64, v interface{}) bool {
+	body, err := io.ReadAll(io.LimitReader(r.Body, limit))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "read_body", err.Error())
+		return false
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_json", err.Error())
+		return false
+	}
+	return true
+}
+
+// cliFailure reports a failed run with the end of its stderr, where the
+// CLI prints its error
+func cliFailure(code string, res cliResult) *apiError {
+	msg := strings.TrimSpace(tail(res.Stderr, 2000))
+	if msg == "" {
+		msg = fmt.Sprintf("clanker exited with status %d", res.ExitCode)
+	}
+	return &apiError{Code: code, Message: msg}
+}
+
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeClanker stands in for the clanker binary: it echoes its arguments
+// for asks and prints what ask --maker, --apply and --route-only print
+const fakeClanker = `#!/bin/sh
+case "$*" in
+  *--maker*) echo '{"summary":"create bucket","commands":[]}'; echo "Saved as plan 20261015-120000-abcd (apply with: clanker plan apply 20261015-120000-abcd)" >&2 ;;
+  *--apply*) echo "running 1/2"; echo "running 2/2"; if [ "$FAIL" = 1 ]; then echo "error: bucket exists" >&2; exit 3; fi ;;
+  *--route-only*) echo '{"agent":"aws","confidence":0.9}' ;;
+  *) echo "args: $*" ;;
+esac
+`
+
+func newAskServer(t *testing.T, cfg Config) *Server {
+	t.Helper()
+	exe := filepath.Join(t.TempDir(), "clanker")
+	if err := os.WriteFile(exe, []byte(fakeClanker), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Token = "test-token"
+	cfg.Executable = exe
+	return New(cfg, log.New(io.Discard, "", 0))
+}
+
+func serve(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec := httptest.NewRecorder()
+	s.middleware(s.mux).ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeData(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	var env struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil {
+		t.Fatalf("body %q: %v", rec.Body.String(), err)
+	}
+	if err := json.Unmarshal(env.Data, v); err != nil {
+		t.Fatalf("data %q: %v", env.Data, err)
+	}
+}
+
+func TestAsk(t *testing.T) {
+	s := newAskServer(t, Config{Flags: []string{"--config", "/etc/clanker.yaml"}})
+	rec := serve(s, http.MethodPost, "/api/v1/ask", `{"question":"--help me list buckets","provider":"AWS","profile":"prod"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var data struct{ Answer string }
+	decodeData(t, rec, &data)
+	if data.Answer != "args: ask --config /etc/clanker.yaml --aws --profile prod -- --help me list buckets\n" {
+		t.Errorf("answer = %q", data.Answer)
+	}
+
+	for body, code := range map[string]string{
+		`{"question":""}`:                         "missing_question",
+		`{"question":"hi","provider":"aws --rm"}`: "unsupported_provider",
+		`not json`: "invalid_json",
+	} {
+		rec := serve(s, http.MethodPost, "/api/v1/ask", body)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), code) {
+			t.Errorf("%s: status %d, body %s", body, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestCreatePlanAndRoute(t *testing.T) {
+	s := newAskServer(t, Config{})
+	rec := serve(s, http.MethodPost, "/api/v1/plans", `{"question":"create a bucket","provider":"aws","destroyer":true}`)
+	var plan struct {
+		ID   string
+		Plan struct{ Summary string }
+	}
+	decodeData(t, rec, &plan)
+	if plan.ID != "20261015-120000-abcd" || plan.Plan.Summary != "create bucket" {
+		t.Errorf("plan = %+v", plan)
+	}
+
+	rec = serve(s, http.MethodGet, "/api/v1/route?question=list+buckets", "")
+	var route struct{ Agent string }
+	decodeData(t, rec, &route)
+	if route.Agent != "aws" {
+		t.Errorf("route = %s", rec.Body.String())
+	}
+}
+
+func TestApplyPlanStreams(t *testing.T) {
+	s := newAskServer(t, Config{Env: []string{"FAIL=1"}})
+	rec := serve(s, http.MethodPost, "/api/v1/plans/20261015-120000-abcd/apply", `{"stream":true}`)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("content type %q: %s", ct, rec.Body.String())
+	}
+	var events []streamEvent
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var ev streamEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) != 4 {
+		t.Fatalf("events = %+v", events)
+	}
+	var stdout []string
+	for _, ev := range events[:3] {
+		if ev.Stream == "stdout" {
+			stdout = append(stdout, ev.Text)
+		}
+	}
+	if strings.Join(stdout, ",") != "running 1/2,running 2/2" {
+		t.Errorf("stdout events = %v", stdout)
+	}
+	done := events[3]
+	data, _ := json.Marshal(done.Data)
+	if !done.Done || *done.ExitCode != 3 || !strings.Contains(string(data), `"status":"error"`) || !strings.Contains(string(data), "bucket exists") {
+		t.Errorf("done = %+v, data %s", done, data)
+	}
+	if h := s.history.list(0); len(h) != 1 || h[0].Summary != "plan 20261015-120000-abcd" {
+		t.Errorf("history = %+v", h)
+	}
+
+	if rec := serve(s, http.MethodPost, "/api/v1/plans/..%2Fetc/apply", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad id status %d", rec.Code)
+	}
+	s.startApply("20261015-120000-abcd")
+	if rec := serve(s, http.MethodPost, "/api/v1/plans/20261015-120000-abcd/apply", ""); rec.Code != http.StatusConflict {
+		t.Errorf("second apply status %d", rec.Code)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	s := newAskServer(t, Config{MaxConcurrent: 1})
+	s.slots <- struct{}{}
+	rec := serve(s, http.MethodPost, "/api/v1/ask", `{"question":"hi"}`)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("status %d, body %s", rec.Code, rec.Body.String())
+	}
+	<-s.slots
+	if rec := serve(s, http.MethodPost, "/api/v1/ask", `{"question":"hi"}`); rec.Code != http.StatusOK {
+		t.Errorf("status after release %d", rec.Code)
+	}
+}
+
+func TestAPIKeyHeader(t *testing.T) {
+	s := newAskServer(t, Config{})
+	for key, want := range map[string]int{"test-token": http.StatusOK, "wrong": http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/version", nil)
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		s.middleware(s.mux).ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("X-API-Key %s: status %d, want %d", key, rec.Code, want)
+		}
+	}
+}
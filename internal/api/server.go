@@ -15,6 +15,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -29,6 +30,19 @@ type Config struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	Debug        bool
+
+	// MaxConcurrent caps the ask, plan, apply and route requests running
+	// at once; each runs a clanker process. Defaults to 4.
+	MaxConcurrent int
+	// Executable is the clanker binary those requests run; defaults to
+	// the running binary
+	Executable string
+	// Env is added to the environment of each clanker process, e.g. the
+	// AI settings given to the server as flags
+	Env []string
+	// Flags follow the subcommand of each clanker process, e.g. the
+	// server's own --config
+	Flags []string
 }
 
 // Server wraps an *http.Server plus the routes the API exposes. Build it
@@ -39,6 +53,12 @@ type Server struct {
 	logger  *log.Logger
 	started time.Time
 	history *history
+
+	// slots holds one token per running clanker process
+	slots chan struct{}
+	// applying holds the plan IDs being applied
+	applyMu  sync.Mutex
+	applying map[string]bool
 }
 
 // New constructs a Server with the standard route set. Call Run to start.
@@ -62,10 +82,21 @@ func New(cfg Config, logger *log.Logger) *Server {
 	if cfg.WriteTimeout == 0 {
 		cfg.WriteTimeout = 90 * time.Second
 	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 4
+	}
 	if logger == nil {
 		logger = log.Default()
 	}
-	s := &Server{cfg: cfg, mux: http.NewServeMux(), logger: logger, started: time.Now(), history: newHistory()}
+	s := &Server{
+		cfg:      cfg,
+		mux:      http.NewServeMux(),
+		logger:   logger,
+		started:  time.Now(),
+		history:  newHistory(),
+		slots:    make(chan struct{}, cfg.MaxConcurrent),
+		applying: map[string]bool{},
+	}
 	s.registerRoutes()
 	return s
 }
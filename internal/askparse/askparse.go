@@ -0,0 +1,85 @@
+// Package askparse holds the question parsing the provider agents share
+// when they answer an ask question directly: picking a name out of the
+// question, reading the environment variable names it mentions, and
+// telling a request for a change from a question about how to make one.
+// Each provider keeps its own patterns and verb table.
+package askparse
+
+import (
+	"regexp"
+	"strings"
+)
+
+// commonNotNames are words every provider's name patterns pick up in
+// ordinary phrasing
+var commonNotNames = []string{
+	"a", "an", "the", "my", "our", "this", "that", "all", "which", "what", "is",
+	"of", "to", "in", "on", "for", "with", "from", "and",
+}
+
+// NotNames returns the words a name pattern must skip: the common ones
+// plus a provider's own, such as its product name and resource nouns
+func NotNames(extra ...string) map[string]bool {
+	words := make(map[string]bool, len(commonNotNames)+len(extra))
+	for _, w := range commonNotNames {
+		words[w] = true
+	}
+	for _, w := range extra {
+		words[w] = true
+	}
+	return words
+}
+
+// Names finds the name a question gives a resource
+type Names struct {
+	// Patterns are tried in order; the first group of a match is the name
+	Patterns []*regexp.Regexp
+	// Skip are lower-case words that never name the resource
+	Skip map[string]bool
+	// Trim are trailing characters dropped from a match, such as the
+	// period ending a sentence
+	Trim string
+	// Reject drops matches Skip cannot list, such as region names
+	Reject func(name string) bool
+}
+
+// Find returns the first match that could name the resource, or ""
+func (n Names) Find(question string) string {
+	for _, re := range n.Patterns {
+		for _, m := range re.FindAllStringSubmatch(question, -1) {
+			name := strings.TrimRight(m[1], n.Trim)
+			if name == "" || n.Skip[strings.ToLower(name)] || (n.Reject != nil && n.Reject(name)) {
+				continue
+			}
+			return name
+		}
+	}
+	return ""
+}
+
+// keyNameRe matches names such as DATABASE_URL, or KEY in KEY=VALUE
+var keyNameRe = regexp.MustCompile(`\b([A-Z][A-Z0-9]*(?:_[A-Z0-9]+)+)\b|\b([A-Z][A-Z0-9_]*)=`)
+
+// KeyNames returns the environment variable or secret names question
+// mentions, in order and without repeats. Values are never read.
+func KeyNames(question string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range keyNameRe.FindAllStringSubmatch(question, -1) {
+		name := m[1] + m[2]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// howToRe starts a question about how or whether to make a change
+var howToRe = regexp.MustCompile(`(?i)^\s*(?:how|why|what|when|should|does|is)\b`)
+
+// AsksHowTo reports whether question asks how to make a change rather than
+// asking for it, so a parser that found a change returns no request
+func AsksHowTo(question string) bool {
+	return howToRe.MatchString(question)
+}
@@ -0,0 +1,49 @@
+package askparse
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestNamesFind(t *testing.T) {
+	names := Names{
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)\bapp\s+([\w.-]+)`),
+			regexp.MustCompile(`(?i)\b([\w.-]+)\s+app\b`),
+		},
+		Skip:   NotNames("fly"),
+		Trim:   ".",
+		Reject: func(name string) bool { return name == "iad" },
+	}
+	for question, want := range map[string]string{
+		"scale the app web-api.":     "web-api",
+		"deploy my fly app":          "",
+		"logs for the billing app":   "billing",
+		"restart app iad in app api": "api",
+	} {
+		if got := names.Find(question); got != want {
+			t.Errorf("Find(%q) = %q, want %q", question, got, want)
+		}
+	}
+}
+
+func TestKeyNames(t *testing.T) {
+	got := KeyNames("set DATABASE_URL and API_KEY=abc, then DATABASE_URL again with TOKEN=x")
+	if want := []string{"DATABASE_URL", "API_KEY", "TOKEN"}; !slices.Equal(got, want) {
+		t.Errorf("KeyNames = %v, want %v", got, want)
+	}
+}
+
+func TestAsksHowTo(t *testing.T) {
+	for question, want := range map[string]bool{
+		"how do I redeploy web":     true,
+		"  Should I scale api to 3": true,
+		"redeploy web":              false,
+		"show how many apps":        false,
+	} {
+		if got := AsksHowTo(question); got != want {
+			t.Errorf("AsksHowTo(%q) = %v, want %v", question, got, want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+// Package audit keeps an append-only log of every change clanker makes to
+// cloud or cluster resources. Each clanker invocation that runs a mutating
+// kubectl, helm, eksctl, aws, gcloud or az command appends one JSON line to
+// ~/.clanker/audit.log (mode 0600) with who ran it, the plan it applied and
+// every mutating step with its exit status. Policy overrides and approvals,
+// and deploys of images past the vulnerability scan gate, are recorded in
+// the same log.
+package audit
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+)
+
+// Event kinds
+const (
+	EventApply             = "apply"
+	EventPolicyOverride    = "policy_override"
+	EventPolicyApproval    = "policy_approval"
+	EventImageScanOverride = "image_scan_override"
+)
+
+// Record is one audit log entry
+type Record struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	User       string    `json:"user"`
+	Command    string    `json:"command"` // clanker command and the flags it was given, without values
+	Provider   string    `json:"provider,omitempty"`
+	PlanHash   string    `json:"plan_hash,omitempty"` // sha256 of the applied plan JSON
+	Summary    string    `json:"summary,omitempty"`
+	Steps      []Step    `json:"steps,omitempty"`
+	Details    []string  `json:"details,omitempty"` // e.g. the policy violations that were overridden
+	ExitStatus int       `json:"exit_status"`
+	Error      string    `json:"error,omitempty"`
+	Duration   string    `json:"duration,omitempty"`
+}
+
+// Failed reports whether the audited command failed
+func (r Record) Failed() bool {
+	return r.ExitStatus != 0
+}
+
+// Step is one mutating command clanker executed
+type Step struct {
+	Time       time.Time `json:"time"`
+	Tool       string    `json:"tool"`
+	Args       []string  `json:"args"`
+	ExitStatus int       `json:"exit_status"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Path returns ~/.clanker/audit.log
+func Path() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "audit.log"), nil
+}
+
+// Append fills in the ID, time and user when missing and appends rec to the
+// audit log
+func Append(rec Record) (Record, error) {
+	if rec.ID == "" {
+		rec.ID = newID()
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now().UTC()
+	}
+	if rec.User == "" {
+		rec.User = CurrentUser()
+	}
+
+	logPath, err := Path()
+	if err != nil {
+		return rec, err
+	}
+	if err := secfile.EnsurePrivateDir(filepath.Dir(logPath)); err != nil {
+		return rec, err
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return rec, err
+	}
+	f, err := secfile.OpenPrivate(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return rec, err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return rec, err
+	}
+	return rec, f.Close()
+}
+
+// List returns the audit records, oldest first. A missing log is empty.
+func List() ([]Record, error) {
+	logPath, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(logPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return read(f)
+}
+
+func read(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return records, fmt.Errorf("audit log line %d: %w", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// Find returns the record whose ID starts with prefix
+func Find(prefix string) (Record, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return Record{}, fmt.Errorf("audit record id is required")
+	}
+	records, err := List()
+	if err != nil {
+		return Record{}, err
+	}
+	var matches []Record
+	for _, rec := range records {
+		if rec.ID == prefix {
+			return rec, nil
+		}
+		if strings.HasPrefix(rec.ID, prefix) {
+			matches = append(matches, rec)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Record{}, fmt.Errorf("no audit record %q", prefix)
+	case 1:
+		return matches[0], nil
+	}
+	return Record{}, fmt.Errorf("audit record id %q is ambiguous (%d matches)", prefix, len(matches))
+}
+
+// PlanHash returns the sha256 of a plan document
+func PlanHash(plan []byte) string {
+	sum := sha256.Sum256(plan)
+	return hex.EncodeToString(sum[:])
+}
+
+// CurrentUser names the operator for audit records
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+func newID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// ExitStatus returns a command's exit code: 0 on success, the process exit
+// code when it ran and failed, and -1 when it could not run
+func ExitStatus(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// session collects the mutating steps of the running clanker invocation
+var session struct {
+	mu       sync.Mutex
+	active   bool
+	started  time.Time
+	provider string
+	planHash string
+	summary  string
+	steps    []Step
+}
+
+// Begin starts recording steps for this invocation. Steps recorded outside
+// Begin and End are ignored.
+func Begin() {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.active = true
+	session.started = time.Now()
+	session.provider = ""
+	session.planHash = ""
+	session.summary = ""
+	session.steps = nil
+}
+
+// SetPlan attaches the plan being applied to this invocation's record
+func SetPlan(provider string, plan []byte, summary string) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if !session.active {
+		return
+	}
+	if provider != "" {
+		session.provider = provider
+	}
+	session.planHash = PlanHash(plan)
+	session.summary = summary
+}
+
+// RecordStep records a command clanker ran when it changes resources.
+// Read-only commands are ignored.
+func RecordStep(tool string, args []string, err error) {
+	tool = filepath.Base(tool)
+	if !IsMutation(tool, args) {
+		return
+	}
+	step := Step{
+		Time:       time.Now().UTC(),
+		Tool:       tool,
+		Args:       RedactArgs(args),
+		ExitStatus: ExitStatus(err),
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if !session.active {
+		return
+	}
+	if session.provider == "" {
+		session.provider = toolProvider[tool]
+	}
+	session.steps = append(session.steps, step)
+}
+
+// End writes the record for this invocation when it applied a plan or ran a
+// mutating step, and stops recording
+func End(command string, runErr error) (*Record, error) {
+	session.mu.Lock()
+	if !session.active {
+		session.mu.Unlock()
+		return nil, nil
+	}
+	session.active = false
+	if session.planHash == "" && len(session.steps) == 0 {
+		session.mu.Unlock()
+		return nil, nil
+	}
+	rec := Record{
+		Event:    EventApply,
+		Command:  command,
+		Provider: session.provider,
+		PlanHash: session.planHash,
+		Summary:  session.summary,
+		Steps:    session.steps,
+		Duration: time.Since(session.started).Round(time.Millisecond).String(),
+	}
+	session.steps = nil
+	session.mu.Unlock()
+
+	if runErr != nil {
+		rec.Error = runErr.Error()
+		rec.ExitStatus = 1
+	}
+	rec, err := Append(rec)
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
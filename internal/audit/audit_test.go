@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSessionRecordsMutatingSteps(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// Steps outside a session are dropped
+	RecordStep("kubectl", []string{"delete", "pod", "web"}, nil)
+
+	Begin()
+	SetPlan("kubernetes", []byte(`{"summary":"scale web"}`), "scale web")
+	RecordStep("kubectl", []string{"get", "pods"}, nil)
+	RecordStep("/usr/local/bin/kubectl", []string{"scale", "deploy/web", "--replicas", "3", "-n", "shop"}, nil)
+	failure := exec.Command("sh", "-c", "exit 3").Run()
+	RecordStep("helm", []string{"upgrade", "--install", "web", "bitnami/nginx", "--set", "auth.password=hunter2"}, failure)
+	rec, err := End("clanker ask --apply", errors.New("helm command failed"))
+	if err != nil {
+		t.Fatalf("End: %v", err)
+	}
+	if rec == nil {
+		t.Fatal("End wrote no record")
+	}
+
+	info, err := os.Stat(filepath.Join(home, ".clanker", "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("audit log mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	records, err := List()
+	if err != nil || len(records) != 1 {
+		t.Fatalf("List() = %d records, %v", len(records), err)
+	}
+	got := records[0]
+	if got.ID != rec.ID || got.Event != EventApply || got.Command != "clanker ask --apply" || got.Provider != "kubernetes" || got.User == "" {
+		t.Errorf("record = %+v", got)
+	}
+	if got.PlanHash != PlanHash([]byte(`{"summary":"scale web"}`)) || got.Summary != "scale web" {
+		t.Errorf("plan = %q %q", got.PlanHash, got.Summary)
+	}
+	if !got.Failed() || got.ExitStatus != 1 || got.Error != "helm command failed" {
+		t.Errorf("status = %d %q", got.ExitStatus, got.Error)
+	}
+	if len(got.Steps) != 2 {
+		t.Fatalf("steps = %+v, want the two mutations", got.Steps)
+	}
+	if got.Steps[0].Tool != "kubectl" || got.Steps[0].ExitStatus != 0 {
+		t.Errorf("step 1 = %+v", got.Steps[0])
+	}
+	if got.Steps[1].ExitStatus != 3 || strings.Contains(strings.Join(got.Steps[1].Args, " "), "hunter2") {
+		t.Errorf("step 2 = %+v", got.Steps[1])
+	}
+
+	// A session without mutations writes nothing
+	Begin()
+	RecordStep("aws", []string{"ec2", "describe-instances"}, nil)
+	if rec, err := End("clanker ask", nil); rec != nil || err != nil {
+		t.Errorf("read-only session wrote %v, %v", rec, err)
+	}
+	if records, _ := List(); len(records) != 1 {
+		t.Errorf("got %d records, want 1", len(records))
+	}
+}
+
+func TestFind(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, id := range []string{"abc123", "abd456", "ffff00"} {
+		if _, err := Append(Record{ID: id, Event: EventApply}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if rec, err := Find("ff"); err != nil || rec.ID != "ffff00" {
+		t.Errorf("Find(ff) = %v, %v", rec.ID, err)
+	}
+	if _, err := Find("ab"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Find(ab) error = %v", err)
+	}
+	if _, err := Find("zz"); err == nil {
+		t.Error("Find(zz) should fail")
+	}
+}
+
+func TestListWithoutLog(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if records, err := List(); err != nil || records != nil {
+		t.Errorf("List() = %v, %v", records, err)
+	}
+}
+
+func TestIsMutation(t *testing.T) {
+	tests := []struct {
+		tool string
+		args []string
+		want bool
+	}{
+		{"kubectl", []string{"get", "pods", "-A"}, false},
+		{"kubectl", []string{"--context", "prod", "-n", "shop", "delete", "pod", "web"}, true},
+		{"kubectl", []string{"apply", "-f", "-", "--dry-run=server"}, false},
+		{"kubectl", []string{"rollout", "status", "deploy/web"}, false},
+		{"kubectl", []string{"rollout", "restart", "deploy/web"}, true},
+		{"helm", []string{"list", "-A"}, false},
+		{"helm", []string{"repo", "add", "bitnami", "https://charts.bitnami.com/bitnami"}, true},
+		{"helm", []string{"uninstall", "web", "-n", "shop"}, true},
+		{"eksctl", []string{"get", "cluster"}, false},
+		{"eksctl", []string{"create", "cluster", "--name", "dev"}, true},
+		{"aws", []string{"ec2", "describe-instances", "--region", "us-east-1"}, false},
+		{"aws", []string{"--profile", "dev", "ec2", "run-instances", "--image-id", "ami-1"}, true},
+		{"aws", []string{"s3", "ls"}, false},
+		{"aws", []string{"s3", "rm", "s3://bucket/key"}, true},
+		{"aws", []string{"sts", "get-caller-identity"}, false},
+		{"gcloud", []string{"container", "clusters", "list"}, false},
+		{"gcloud", []string{"container", "clusters", "create", "dev", "--zone", "us-central1-a"}, true},
+		{"gcloud", []string{"container", "clusters", "get-credentials", "dev"}, false},
+		{"az", []string{"aks", "show", "-g", "rg", "-n", "dev"}, false},
+		{"az", []string{"account", "set", "--subscription", "sub"}, false},
+		{"az", []string{"group", "delete", "-n", "rg", "--yes"}, true},
+		{"terraform", []string{"apply"}, false},
+	}
+	for _, tt := range tests {
+		if got := IsMutation(tt.tool, tt.args); got != tt.want {
+			t.Errorf("IsMutation(%s %v) = %v, want %v", tt.tool, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRedactArgs(t *testing.T) {
+	args := []string{
+		"create", "secret", "generic", "db", "--from-literal=password=s3cret",
+		"--admin-password", "hunter2", "--token=abc", "--name", "keep",
+	}
+	got := strings.Join(RedactArgs(args), " ")
+	want := "create secret generic db --from-literal=password=[REDACTED] --admin-password [REDACTED] --token=[REDACTED] --name keep"
+	if got != want {
+		t.Errorf("RedactArgs = %q\nwant %q", got, want)
+	}
+	if args[6] != "hunter2" {
+		t.Error("RedactArgs modified its input")
+	}
+}
@@ -0,0 +1,190 @@
+package audit
+
+import (
+	"strings"
+)
+
+// toolProvider maps the audited CLIs to the provider they change
+var toolProvider = map[string]string{
+	"kubectl": "kubernetes",
+	"helm":    "kubernetes",
+	"eksctl":  "aws",
+	"aws":     "aws",
+	"gcloud":  "gcp",
+	"az":      "azure",
+}
+
+// readOnlyKubectl are kubectl and helm commands that never change a cluster
+var readOnlyKubectl = map[string]bool{
+	"get": true, "describe": true, "logs": true, "top": true, "explain": true,
+	"api-resources": true, "api-versions": true, "version": true, "cluster-info": true,
+	"config": true, "auth": true, "wait": true, "diff": true, "events": true,
+	"port-forward": true, "proxy": true, "completion": true, "kustomize": true,
+	"status": true, "history": true, "list": true, "search": true, "show": true,
+	"repo": true, "template": true, "lint": true, "dependency": true, "plugin": true,
+	"env": true, "help": true, "verify": true, "pull": true, "get-values": true,
+}
+
+// readOnlyEksctl are eksctl commands that never change a cluster
+var readOnlyEksctl = map[string]bool{
+	"get": true, "info": true, "version": true, "help": true, "completion": true,
+}
+
+// readOnlyCloudVerbs are gcloud and az command words that only read, plus
+// local CLI configuration commands
+var readOnlyCloudVerbs = map[string]bool{
+	"list": true, "describe": true, "show": true, "info": true, "version": true,
+	"help": true, "config": true, "auth": true, "account": true, "login": true,
+	"read": true, "tail": true, "wait": true, "exists": true, "query": true,
+	"check-name-availability": true, "get-credentials": true,
+	"print-access-token": true, "print-identity-token": true,
+}
+
+// IsMutation reports whether a CLI invocation can change cloud or cluster
+// resources. Tools clanker does not audit return false.
+func IsMutation(tool string, args []string) bool {
+	positional := positionalArgs(args)
+	if len(positional) == 0 {
+		return false
+	}
+	switch tool {
+	case "kubectl", "helm":
+		verb := positional[0]
+		if (verb == "rollout" || verb == "repo" || verb == "plugin") && len(positional) > 1 {
+			verb = positional[1]
+		}
+		if isDryRun(args) {
+			return false
+		}
+		return !readOnlyKubectl[verb]
+	case "eksctl":
+		return !readOnlyEksctl[positional[0]]
+	case "aws":
+		if positional[0] == "configure" || positional[0] == "help" {
+			return false
+		}
+		if len(positional) < 2 {
+			return false
+		}
+		op := positional[1]
+		if positional[0] == "s3" {
+			return op != "ls" && op != "presign"
+		}
+		for _, prefix := range []string{"describe-", "list-", "get-", "wait", "batch-get-", "search-", "lookup-", "help", "scan", "query", "filter-", "head-", "test-", "simulate-", "validate-"} {
+			if strings.HasPrefix(op, prefix) {
+				return false
+			}
+		}
+		return true
+	case "gcloud", "az":
+		for _, word := range positional {
+			if readOnlyCloudVerbs[word] || strings.HasPrefix(word, "get-") {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// positionalArgs drops flags and their values, approximately: a flag is
+// assumed to take the next argument unless that argument is another flag
+func positionalArgs(args []string) []string {
+	var out []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") {
+			if !strings.Contains(arg, "=") && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") && takesValue(arg) {
+				i++
+			}
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
+// booleanFlags are common flags that do not take a value
+var booleanFlags = map[string]bool{
+	"-A": true, "--all-namespaces": true, "--all": true, "--force": true, "--wait": true,
+	"--install": true, "--atomic": true, "--overwrite": true, "--recursive": true,
+	"--no-cli-pager": true, "--quiet": true, "-q": true, "--yes": true, "-y": true,
+	"--ignore-daemonsets": true, "--delete-emptydir-data": true, "--create-namespace": true,
+	"--reuse-values": true, "--reset-values": true, "--ignore-not-found": true, "--no-wait": true,
+	"--only-show-errors": true, "--async": true, "--debug": true, "--approve": true,
+}
+
+func takesValue(flag string) bool {
+	return !booleanFlags[flag]
+}
+
+func isDryRun(args []string) bool {
+	for _, arg := range args {
+		if arg == "--dry-run" || (strings.HasPrefix(arg, "--dry-run=") && arg != "--dry-run=none") {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitiveFlagMarkers identify flags whose values must not reach the log
+var sensitiveFlagMarkers = []string{"password", "secret", "token", "credential", "from-literal", "private-key"}
+
+// RedactArgs replaces the values of password, token and secret flags, and
+// of key=value arguments such as helm --set values with a sensitive key
+func RedactArgs(args []string) []string {
+	out := make([]string, len(args))
+	copy(out, args)
+	for i := 0; i < len(out); i++ {
+		arg := out[i]
+		if !strings.HasPrefix(arg, "-") {
+			out[i] = redactAssignment(arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !isSensitiveFlag(name) {
+			if hasValue {
+				out[i] = name + "=" + redactAssignment(value)
+			}
+			continue
+		}
+		if hasValue {
+			if strings.Contains(name, "from-literal") {
+				// --from-literal=key=value keeps the key
+				if key, _, ok := strings.Cut(value, "="); ok {
+					out[i] = name + "=" + key + "=[REDACTED]"
+					continue
+				}
+			}
+			out[i] = name + "=[REDACTED]"
+			continue
+		}
+		if i+1 < len(out) && !strings.HasPrefix(out[i+1], "-") {
+			out[i+1] = "[REDACTED]"
+			i++
+		}
+	}
+	return out
+}
+
+// redactAssignment redacts the value of key=value when the key is sensitive
+func redactAssignment(arg string) string {
+	key, _, ok := strings.Cut(arg, "=")
+	if !ok || !isSensitiveFlag(key) {
+		return arg
+	}
+	return key + "=[REDACTED]"
+}
+
+func isSensitiveFlag(arg string) bool {
+	name, _, _ := strings.Cut(strings.ToLower(arg), "=")
+	for _, marker := range sensitiveFlagMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -16,11 +17,16 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/spf13/viper"
 )
 
@@ -37,6 +43,9 @@ type Client struct {
 	batch          *batch.Client
 	cloudwatch     *cloudwatch.Client
 	cloudwatchlogs *cloudwatchlogs.Client
+	// cliEnv, when set, holds the assumed-role session credentials CLI calls
+	// run with in place of --profile
+	cliEnv []string
 }
 
 func NewClient(ctx context.Context) (*Client, error) {
@@ -87,6 +96,10 @@ func getCredentialsFromCLI(ctx context.Context, profile string) (*awsCredentials
 		return nil, fmt.Errorf("aws profile is empty")
 	}
 
+	if creds := cachedCLICredentialsFor(profile); creds != nil {
+		return creds, nil
+	}
+
 	// For SSO profiles, use export-credentials with process format
 	cmd := exec.CommandContext(ctx, "aws", "configure", "export-credentials", "--profile", profile, "--format", "process")
 	cmd.Env = append(os.Environ(), fmt.Sprintf("AWS_PROFILE=%s", profile))
@@ -101,9 +114,48 @@ func getCredentialsFromCLI(ctx context.Context, profile string) (*awsCredentials
 		return nil, fmt.Errorf("failed to parse AWS CLI credentials response: %w", err)
 	}
 
+	storeCLICredentials(profile, &creds)
 	return &creds, nil
 }
 
+// CLI credential caching. Exporting credentials shells out to the AWS CLI,
+// which takes seconds for SSO profiles; one-shot commands only call it once,
+// but the warm daemon reuses the result until shortly before it expires.
+const (
+	cliCredentialRefreshMargin = 5 * time.Minute
+	cliCredentialDefaultTTL    = 15 * time.Minute
+)
+
+var cliCredentialCache = struct {
+	sync.Mutex
+	entries map[string]cachedCLICredentials
+}{entries: map[string]cachedCLICredentials{}}
+
+type cachedCLICredentials struct {
+	creds   *awsCredentialsFromCLI
+	expires time.Time
+}
+
+func cachedCLICredentialsFor(profile string) *awsCredentialsFromCLI {
+	cliCredentialCache.Lock()
+	defer cliCredentialCache.Unlock()
+	entry, ok := cliCredentialCache.entries[profile]
+	if !ok || time.Now().After(entry.expires) {
+		return nil
+	}
+	return entry.creds
+}
+
+func storeCLICredentials(profile string, creds *awsCredentialsFromCLI) {
+	expires := time.Now().Add(cliCredentialDefaultTTL)
+	if t, err := time.Parse(time.RFC3339, creds.Expiration); err == nil {
+		expires = t.Add(-cliCredentialRefreshMargin)
+	}
+	cliCredentialCache.Lock()
+	cliCredentialCache.entries[profile] = cachedCLICredentials{creds: creds, expires: expires}
+	cliCredentialCache.Unlock()
+}
+
 func NewClientWithProfile(ctx context.Context, profile string) (*Client, error) {
 	return NewClientWithProfileAndDebug(ctx, profile, false)
 }
@@ -219,15 +271,44 @@ func NewClientWithCredentials(ctx context.Context, creds *BackendAWSCredentials,
 	}, nil
 }
 
+// withRegion returns a copy of the client whose SDK service clients target
+// another region. CLI-backed operations keep the profile's region.
+func (c *Client) withRegion(region string) *Client {
+	cfg := c.cfg.Copy()
+	cfg.Region = region
+	return &Client{
+		cfg:            cfg,
+		profile:        c.profile,
+		debug:          c.debug,
+		cliEnv:         c.cliEnv,
+		ec2:            ec2.NewFromConfig(cfg),
+		ecs:            ecs.NewFromConfig(cfg),
+		iam:            iam.NewFromConfig(cfg),
+		lambda:         lambda.NewFromConfig(cfg),
+		rds:            rds.NewFromConfig(cfg),
+		s3:             s3.NewFromConfig(cfg),
+		batch:          batch.NewFromConfig(cfg),
+		cloudwatch:     cloudwatch.NewFromConfig(cfg),
+		cloudwatchlogs: cloudwatchlogs.NewFromConfig(cfg),
+	}
+}
+
 func (c *Client) GetRelevantContext(ctx context.Context, question string) (string, error) {
 	var context strings.Builder
 
 	// Analyze question to determine what AWS services to query
 	questionLower := strings.ToLower(question)
 	hasSecurityQuery := awsQuestionHasSecurityIntent(questionLower)
+	filter := contextFilterFromQuestion(question)
+
+	// A single explicit region ("in us-west-2") retargets the SDK listings
+	if region := filter.AWSRegion(); region != "" && region != c.cfg.Region {
+		c = c.withRegion(region)
+		context.WriteString(fmt.Sprintf("Region: %s (from question)\n\n", region))
+	}
 
 	if strings.Contains(questionLower, "ec2") || strings.Contains(questionLower, "instance") {
-		ec2Info, err := c.getEC2Info(ctx)
+		ec2Info, err := c.getEC2Info(ctx, filter)
 		if err != nil {
 			return "", fmt.Errorf("failed to get EC2 info: %w", err)
 		}
@@ -237,7 +318,7 @@ func (c *Client) GetRelevantContext(ctx context.Context, question string) (strin
 	}
 
 	if strings.Contains(questionLower, "lambda") || strings.Contains(questionLower, "function") {
-		lambdaInfo, err := c.getLambdaInfo(ctx)
+		lambdaInfo, err := c.getLambdaInfo(ctx, filter)
 		if err != nil {
 			return "", fmt.Errorf("failed to get Lambda info: %w", err)
 		}
@@ -247,7 +328,7 @@ func (c *Client) GetRelevantContext(ctx context.Context, question string) (strin
 	}
 
 	if strings.Contains(questionLower, "rds") || strings.Contains(questionLower, "database") {
-		rdsInfo, err := c.getRDSInfo(ctx)
+		rdsInfo, err := c.getRDSInfo(ctx, filter)
 		if err != nil {
 			return "", fmt.Errorf("failed to get RDS info: %w", err)
 		}
@@ -257,7 +338,7 @@ func (c *Client) GetRelevantContext(ctx context.Context, question string) (strin
 	}
 
 	if strings.Contains(questionLower, "s3") || strings.Contains(questionLower, "bucket") {
-		s3Info, err := c.getS3Info(ctx)
+		s3Info, err := c.getS3Info(ctx, filter)
 		if err != nil {
 			return "", fmt.Errorf("failed to get S3 info: %w", err)
 		}
@@ -267,7 +348,7 @@ func (c *Client) GetRelevantContext(ctx context.Context, question string) (strin
 	}
 
 	if strings.Contains(questionLower, "ecs") || strings.Contains(questionLower, "container") {
-		ecsInfo, err := c.getECSInfo(ctx)
+		ecsInfo, err := c.getECSInfo(ctx, filter)
 		if err != nil {
 			return "", fmt.Errorf("failed to get ECS info: %w", err)
 		}
@@ -281,6 +362,7 @@ func (c *Client) GetRelevantContext(ctx context.Context, question string) (strin
 		op   string
 		keys []string
 	}{
+		{name: "Amazon EKS", op: "list_eks_clusters", keys: []string{"eks", "kubernetes cluster", "kubernetes clusters"}},
 		{name: "App Runner Services", op: "list_apprunner_services", keys: []string{"app runner", "apprunner"}},
 		{name: "AWS Resource Explorer", op: "search_resource_explorer", keys: []string{"resource explorer", "all resources", "inventory", "what resources"}},
 		{name: "Tagged Resources", op: "list_tagged_resources", keys: []string{"tagged resources", "resource tags", "tags"}},
@@ -328,7 +410,7 @@ func (c *Client) GetRelevantContext(ctx context.Context, question string) (strin
 	}
 
 	if strings.Contains(questionLower, "iam") || strings.Contains(questionLower, "role") {
-		rolesInfo, err := c.getIAMRolesInfo(ctx)
+		rolesInfo, err := c.getIAMRolesInfo(ctx, filter)
 		if err != nil {
 			return "", fmt.Errorf("failed to get IAM roles info: %w", err)
 		}
@@ -432,80 +514,131 @@ func awsQuestionHasSecurityIntent(questionLower string) bool {
 	return false
 }
 
-func (c *Client) getIAMRolesInfo(ctx context.Context) (string, error) {
-	// IAM is global; this lists roles in the account. Keep output bounded.
-	const maxRoles = 100
+func (c *Client) getIAMRolesInfo(ctx context.Context, filter contextFilter) (string, error) {
+	var roles []iamtypes.Role
+	paginator := iam.NewListRolesPaginator(c.iam, &iam.ListRolesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+		roles = append(roles, page.Roles...)
+	}
+
+	if len(roles) == 0 {
+		return "(no roles found)\n", nil
+	}
+
+	// ListRoles does not return tags, so only name hints apply here.
+	selected, footer := filterContextItems(filter, roles, contextItemFields[iamtypes.Role]{
+		name:    func(r iamtypes.Role) string { return aws.ToString(r.RoleName) },
+		created: func(r iamtypes.Role) time.Time { return aws.ToTime(r.CreateDate) },
+	})
 
 	var info strings.Builder
-	count := 0
-	var marker *string
+	for _, role := range selected {
+		info.WriteString(fmt.Sprintf("- Role: %s, Arn: %s, Created: %s\n",
+			aws.ToString(role.RoleName),
+			aws.ToString(role.Arn),
+			aws.ToTime(role.CreateDate).Format(time.RFC3339)))
+	}
+	info.WriteString(footer)
+	return info.String(), nil
+}
 
-	for {
-		out, err := c.iam.ListRoles(ctx, &iam.ListRolesInput{Marker: marker})
+func (c *Client) getEC2Info(ctx context.Context, filter contextFilter) (string, error) {
+	// Tag hints become server-side filters; name hints match the Name tag
+	// and instance ID client-side.
+	var instances []ec2types.Instance
+	paginator := ec2.NewDescribeInstancesPaginator(c.ec2, &ec2.DescribeInstancesInput{Filters: filter.EC2Filters()})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
 			return "", err
 		}
+		for _, reservation := range page.Reservations {
+			instances = append(instances, reservation.Instances...)
+		}
+	}
 
-		for _, role := range out.Roles {
-			info.WriteString(fmt.Sprintf("- Role: %s, Arn: %s, Created: %s\n",
-				aws.ToString(role.RoleName),
-				aws.ToString(role.Arn),
-				aws.ToTime(role.CreateDate).Format(time.RFC3339)))
-			count++
-			if count >= maxRoles {
-				info.WriteString(fmt.Sprintf("(showing first %d roles)\n", maxRoles))
-				return info.String(), nil
+	selected, footer := filterContextItems(filter, instances, contextItemFields[ec2types.Instance]{
+		name: func(i ec2types.Instance) string { return aws.ToString(i.InstanceId) + " " + ec2InstanceName(i) },
+		tags: func(i ec2types.Instance) map[string]string {
+			tags := make(map[string]string, len(i.Tags))
+			for _, tag := range i.Tags {
+				tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
 			}
-		}
+			return tags
+		},
+		created: func(i ec2types.Instance) time.Time { return aws.ToTime(i.LaunchTime) },
+	})
 
-		if out.IsTruncated && out.Marker != nil {
-			marker = out.Marker
+	var info strings.Builder
+	for _, instance := range selected {
+		state := ""
+		if instance.State != nil {
+			state = string(instance.State.Name)
+		}
+		if name := ec2InstanceName(instance); name != "" {
+			info.WriteString(fmt.Sprintf("- Instance ID: %s, Name: %s, Type: %s, State: %s\n",
+				aws.ToString(instance.InstanceId), name, string(instance.InstanceType), state))
 			continue
 		}
-		break
+		info.WriteString(fmt.Sprintf("- Instance ID: %s, Type: %s, State: %s\n",
+			aws.ToString(instance.InstanceId),
+			string(instance.InstanceType),
+			state))
 	}
+	info.WriteString(footer)
 
-	if count == 0 {
-		return "(no roles found)\n", nil
-	}
 	return info.String(), nil
 }
 
-func (c *Client) getEC2Info(ctx context.Context) (string, error) {
-	result, err := c.ec2.DescribeInstances(ctx, &ec2.DescribeInstancesInput{})
-	if err != nil {
-		return "", err
-	}
-
-	var info strings.Builder
-	for _, reservation := range result.Reservations {
-		for _, instance := range reservation.Instances {
-			info.WriteString(fmt.Sprintf("- Instance ID: %s, Type: %s, State: %s\n",
-				aws.ToString(instance.InstanceId),
-				string(instance.InstanceType),
-				string(instance.State.Name)))
+func ec2InstanceName(instance ec2types.Instance) string {
+	for _, tag := range instance.Tags {
+		if aws.ToString(tag.Key) == "Name" {
+			return aws.ToString(tag.Value)
 		}
 	}
-
-	return info.String(), nil
+	return ""
 }
 
-func (c *Client) getLambdaInfo(ctx context.Context) (string, error) {
-	result, err := c.lambda.ListFunctions(ctx, &lambda.ListFunctionsInput{})
-	if err != nil {
-		return "", err
+// lambdaURLLookupLimit bounds the per-function GetFunctionUrlConfig calls
+const lambdaURLLookupLimit = 50
+
+func (c *Client) getLambdaInfo(ctx context.Context, filter contextFilter) (string, error) {
+	var functions []lambdatypes.FunctionConfiguration
+	paginator := lambda.NewListFunctionsPaginator(c.lambda, &lambda.ListFunctionsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+		functions = append(functions, page.Functions...)
 	}
 
+	// ListFunctions does not return tags, so only name hints apply here.
+	selected, footer := filterContextItems(filter, functions, contextItemFields[lambdatypes.FunctionConfiguration]{
+		name: func(f lambdatypes.FunctionConfiguration) string { return aws.ToString(f.FunctionName) },
+		created: func(f lambdatypes.FunctionConfiguration) time.Time {
+			modified, _ := time.Parse("2006-01-02T15:04:05.000-0700", aws.ToString(f.LastModified))
+			return modified
+		},
+		size: func(f lambdatypes.FunctionConfiguration) int64 { return f.CodeSize },
+	})
+
 	var info strings.Builder
-	for _, function := range result.Functions {
+	for i, function := range selected {
 		url := ""
 		auth := ""
-		urlCfg, err := c.lambda.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{
-			FunctionName: function.FunctionName,
-		})
-		if err == nil {
-			url = aws.ToString(urlCfg.FunctionUrl)
-			auth = string(urlCfg.AuthType)
+		if i < lambdaURLLookupLimit {
+			urlCfg, err := c.lambda.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{
+				FunctionName: function.FunctionName,
+			})
+			if err == nil {
+				url = aws.ToString(urlCfg.FunctionUrl)
+				auth = string(urlCfg.AuthType)
+			}
 		}
 
 		if url != "" {
@@ -522,62 +655,110 @@ func (c *Client) getLambdaInfo(ctx context.Context) (string, error) {
 				aws.ToString(function.LastModified)))
 		}
 	}
+	info.WriteString(footer)
 
 	return info.String(), nil
 }
 
-func (c *Client) getRDSInfo(ctx context.Context) (string, error) {
-	result, err := c.rds.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{})
-	if err != nil {
-		return "", err
+func (c *Client) getRDSInfo(ctx context.Context, filter contextFilter) (string, error) {
+	var instances []rdstypes.DBInstance
+	paginator := rds.NewDescribeDBInstancesPaginator(c.rds, &rds.DescribeDBInstancesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+		instances = append(instances, page.DBInstances...)
 	}
 
+	selected, footer := filterContextItems(filter, instances, contextItemFields[rdstypes.DBInstance]{
+		name: func(i rdstypes.DBInstance) string { return aws.ToString(i.DBInstanceIdentifier) },
+		tags: func(i rdstypes.DBInstance) map[string]string {
+			tags := make(map[string]string, len(i.TagList))
+			for _, tag := range i.TagList {
+				tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+			}
+			return tags
+		},
+		created: func(i rdstypes.DBInstance) time.Time { return aws.ToTime(i.InstanceCreateTime) },
+		size:    func(i rdstypes.DBInstance) int64 { return int64(aws.ToInt32(i.AllocatedStorage)) << 30 },
+	})
+
 	var info strings.Builder
-	for _, instance := range result.DBInstances {
+	for _, instance := range selected {
 		info.WriteString(fmt.Sprintf("- DB Instance: %s, Engine: %s, Status: %s\n",
 			aws.ToString(instance.DBInstanceIdentifier),
 			aws.ToString(instance.Engine),
 			aws.ToString(instance.DBInstanceStatus)))
 	}
+	info.WriteString(footer)
 
 	return info.String(), nil
 }
 
-func (c *Client) getS3Info(ctx context.Context) (string, error) {
+func (c *Client) getS3Info(ctx context.Context, filter contextFilter) (string, error) {
+	// ListBuckets returns every bucket in one response; tags would need a
+	// GetBucketTagging call per bucket, so only name hints apply here.
 	result, err := c.s3.ListBuckets(ctx, &s3.ListBucketsInput{})
 	if err != nil {
 		return "", err
 	}
 
+	selected, footer := filterContextItems(filter, result.Buckets, contextItemFields[s3types.Bucket]{
+		name:    func(b s3types.Bucket) string { return aws.ToString(b.Name) },
+		created: func(b s3types.Bucket) time.Time { return aws.ToTime(b.CreationDate) },
+	})
+
 	var info strings.Builder
-	for _, bucket := range result.Buckets {
+	for _, bucket := range selected {
 		info.WriteString(fmt.Sprintf("- Bucket: %s, Created: %s\n",
 			aws.ToString(bucket.Name),
-			bucket.CreationDate.Format("2006-01-02")))
+			aws.ToTime(bucket.CreationDate).Format("2006-01-02")))
 	}
+	info.WriteString(footer)
 
 	return info.String(), nil
 }
 
-func (c *Client) getECSInfo(ctx context.Context) (string, error) {
-	clusters, err := c.ecs.ListClusters(ctx, &ecs.ListClustersInput{})
-	if err != nil {
-		return "", err
+func (c *Client) getECSInfo(ctx context.Context, filter contextFilter) (string, error) {
+	var clusters []string
+	clusterPages := ecs.NewListClustersPaginator(c.ecs, &ecs.ListClustersInput{})
+	for clusterPages.HasMorePages() {
+		page, err := clusterPages.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+		clusters = append(clusters, page.ClusterArns...)
 	}
 
 	var info strings.Builder
-	for _, cluster := range clusters.ClusterArns {
-		services, err := c.ecs.ListServices(ctx, &ecs.ListServicesInput{
+	for _, cluster := range clusters {
+		var services []string
+		servicePages := ecs.NewListServicesPaginator(c.ecs, &ecs.ListServicesInput{
 			Cluster: aws.String(cluster),
 		})
-		if err != nil {
-			continue
+		for servicePages.HasMorePages() {
+			page, err := servicePages.NextPage(ctx)
+			if err != nil {
+				break
+			}
+			services = append(services, page.ServiceArns...)
 		}
 
+		// A name hint that matches the cluster keeps all of its services.
+		serviceFilter := filter
+		if filter.matchesName(cluster) {
+			serviceFilter.NameTerms = nil
+		}
+		selected, footer := filterContextItems(serviceFilter, services, contextItemFields[string]{
+			name: func(arn string) string { return arn },
+		})
+
 		info.WriteString(fmt.Sprintf("Cluster: %s\n", cluster))
-		for _, service := range services.ServiceArns {
+		for _, service := range selected {
 			info.WriteString(fmt.Sprintf("  - Service: %s\n", service))
 		}
+		info.WriteString(footer)
 	}
 
 	return info.String(), nil
@@ -1212,7 +1393,17 @@ func (c *Client) execCLI(ctx context.Context, args []string) (string, error) {
 	return c.execAWSCLI(ctx, args, c.aiProfile())
 }
 
+// Runner runs an aws CLI command and returns its output. ExecCLI is the
+// one the agents under internal/aws use; tests pass fakes.
+type Runner func(ctx context.Context, args []string) (string, error)
+
 // ExecCLI exposes the CLI helper to other packages.
 func (c *Client) ExecCLI(ctx context.Context, args []string) (string, error) {
 	return c.execCLI(ctx, args)
 }
+
+// CLICommand returns the aws CLI command ExecCLI would run for args, for
+// callers that attach a terminal to it, such as Session Manager shells
+func (c *Client) CLICommand(ctx context.Context, args []string) *exec.Cmd {
+	return c.cliCommand(ctx, args, c.aiProfile())
+}
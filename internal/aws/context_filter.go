@@ -0,0 +1,164 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/qfilter"
+)
+
+// maxContextItems caps how many matching resources a single section writes
+// into the LLM context. Listings are always fully paginated; the cap only
+// applies after filtering so relevant resources are never dropped silently.
+const maxContextItems = 200
+
+// contextFilter narrows read-path listings to the resources a question
+// mentions: name prefixes/fragments plus the compiled qualifiers (region,
+// tags, age, size).
+type contextFilter struct {
+	qfilter.Filter
+	NameTerms []string
+}
+
+var (
+	contextQuotedPattern = regexp.MustCompile("[\"'`]([^\"'`]{2,})[\"'`]")
+	contextNamedPattern  = regexp.MustCompile(`(?i)\b(?:named|called|prefix(?:ed)?(?:\s+with)?|starting\s+with|starts\s+with|beginning\s+with|matching)\s+([A-Za-z0-9][\w.-]*)`)
+	contextIdentPattern  = regexp.MustCompile(`\b[a-z0-9]+(?:[-_][a-z0-9]+)+\b`)
+)
+
+// contextFilterIgnoredIdents are hyphenated words that show up in questions
+// but never name a resource.
+var contextFilterIgnoredIdents = map[string]bool{
+	"read-only": true, "up-to-date": true, "real-time": true, "multi-az": true,
+	"cross-account": true, "cross-region": true, "end-to-end": true, "built-in": true,
+}
+
+// contextFilterFromQuestion extracts name and tag hints from a question
+func contextFilterFromQuestion(question string) contextFilter {
+	filter := contextFilter{Filter: qfilter.Compile(question)}
+	seen := map[string]bool{}
+	for _, region := range filter.Regions {
+		seen[region] = true
+	}
+	addTerm := func(term string) {
+		term = strings.ToLower(strings.Trim(term, ".,;:!?*"))
+		if len(term) < 2 || seen[term] || contextFilterIgnoredIdents[term] {
+			return
+		}
+		seen[term] = true
+		filter.NameTerms = append(filter.NameTerms, term)
+	}
+
+	for k, v := range filter.Tags {
+		seen[strings.ToLower(k)] = true
+		seen[strings.ToLower(v)] = true
+	}
+	for _, m := range contextQuotedPattern.FindAllStringSubmatch(question, -1) {
+		addTerm(m[1])
+	}
+	for _, m := range contextNamedPattern.FindAllStringSubmatch(question, -1) {
+		addTerm(m[1])
+	}
+	for _, ident := range contextIdentPattern.FindAllString(strings.ToLower(question), -1) {
+		addTerm(ident)
+	}
+	return filter
+}
+
+// isEmpty reports whether the question carried no usable hints
+func (f contextFilter) isEmpty() bool {
+	return len(f.NameTerms) == 0 && f.Filter.IsEmpty()
+}
+
+// matchesName reports whether a resource name contains any name term. With no
+// name terms every resource matches.
+func (f contextFilter) matchesName(name string) bool {
+	if len(f.NameTerms) == 0 {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, term := range f.NameTerms {
+		if strings.Contains(lower, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe renders the active hints for listing footers
+func (f contextFilter) describe() string {
+	var parts []string
+	if len(f.NameTerms) > 0 {
+		parts = append(parts, "name contains "+strings.Join(f.NameTerms, "|"))
+	}
+	if qualifiers := f.Filter.Describe(); qualifiers != "" {
+		parts = append(parts, qualifiers)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// contextItemFields extracts the filterable attributes of a listed resource.
+// Nil accessors mean the listing does not carry that attribute (or it was
+// already filtered server-side), so the matching qualifier is skipped.
+type contextItemFields[T any] struct {
+	name    func(T) string
+	tags    func(T) map[string]string
+	created func(T) time.Time
+	size    func(T) int64
+}
+
+// filterContextItems applies the filter to a fully paginated listing and caps
+// the result at maxContextItems. If name hints match nothing the remaining
+// listing is kept, since those hints are heuristic; compiled qualifiers are
+// always enforced. The returned footer tells the model how the listing was
+// narrowed.
+func filterContextItems[T any](f contextFilter, items []T, fields contextItemFields[T]) ([]T, string) {
+	var notes []string
+	now := time.Now()
+
+	selected := make([]T, 0, len(items))
+	for _, item := range items {
+		if len(f.Tags) > 0 && fields.tags != nil && !f.MatchesTags(fields.tags(item)) {
+			continue
+		}
+		if fields.created != nil && !f.MatchesAge(fields.created(item), now) {
+			continue
+		}
+		if fields.size != nil && !f.MatchesSize(fields.size(item)) {
+			continue
+		}
+		selected = append(selected, item)
+	}
+	if len(f.Tags) > 0 && fields.tags == nil {
+		notes = append(notes, "(tag qualifiers are not available for this listing)")
+	}
+
+	if len(f.NameTerms) > 0 {
+		named := make([]T, 0, len(selected))
+		for _, item := range selected {
+			if f.matchesName(fields.name(item)) {
+				named = append(named, item)
+			}
+		}
+		if len(named) > 0 {
+			selected = named
+		} else if len(selected) > 0 {
+			notes = append(notes, fmt.Sprintf("(no names matched %s; listing all)", strings.Join(f.NameTerms, "|")))
+		}
+	}
+
+	if !f.isEmpty() && len(selected) < len(items) {
+		notes = append(notes, fmt.Sprintf("(filtered %d of %d by %s)", len(selected), len(items), f.describe()))
+	}
+	if len(selected) > maxContextItems {
+		notes = append(notes, fmt.Sprintf("(showing first %d of %d; mention a name prefix or tag to narrow)", maxContextItems, len(selected)))
+		selected = selected[:maxContextItems]
+	}
+
+	if len(notes) == 0 {
+		return selected, ""
+	}
+	return selected, strings.Join(notes, "\n") + "\n"
+}
@@ -0,0 +1,87 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/qfilter"
+)
+
+func TestContextFilterFromQuestion(t *testing.T) {
+	f := contextFilterFromQuestion(`why is the lambda named billing-worker failing in us-east-1 for functions tagged env=prod starting with "pay"`)
+
+	for _, want := range []string{"billing-worker", "pay"} {
+		found := false
+		for _, term := range f.NameTerms {
+			if term == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("NameTerms %v missing %q", f.NameTerms, want)
+		}
+	}
+	for _, term := range f.NameTerms {
+		if term == "us-east-1" {
+			t.Errorf("region should not become a name term: %v", f.NameTerms)
+		}
+	}
+	if f.Tags["env"] != "prod" {
+		t.Errorf("Tags = %v, want env=prod", f.Tags)
+	}
+
+	if !contextFilterFromQuestion("list my lambda functions").isEmpty() {
+		t.Error("plain question should produce an empty filter")
+	}
+}
+
+func TestFilterContextItems(t *testing.T) {
+	names := make([]string, 0, 500)
+	for i := 0; i < 500; i++ {
+		names = append(names, fmt.Sprintf("svc-%03d", i))
+	}
+	names = append(names, "billing-api", "billing-worker")
+	byName := contextItemFields[string]{name: func(s string) string { return s }}
+
+	selected, footer := filterContextItems(contextFilter{NameTerms: []string{"billing"}}, names, byName)
+	if len(selected) != 2 {
+		t.Fatalf("selected %d, want 2: %v", len(selected), selected)
+	}
+	if !strings.Contains(footer, "filtered 2 of 502") {
+		t.Errorf("footer = %q", footer)
+	}
+
+	// No hints: everything is kept but capped, and the cap is reported.
+	selected, footer = filterContextItems(contextFilter{}, names, byName)
+	if len(selected) != maxContextItems {
+		t.Fatalf("selected %d, want cap %d", len(selected), maxContextItems)
+	}
+	if !strings.Contains(footer, fmt.Sprintf("showing first %d of 502", maxContextItems)) {
+		t.Errorf("footer = %q", footer)
+	}
+
+	// Hints that match nothing fall back to the full listing.
+	selected, footer = filterContextItems(contextFilter{NameTerms: []string{"nomatch"}}, names[:3], byName)
+	if len(selected) != 3 || !strings.Contains(footer, "no names matched") {
+		t.Errorf("selected %v footer %q", selected, footer)
+	}
+
+	// Tag filters apply when the listing carries tags; without tags the
+	// listing is kept and the gap is reported.
+	_, footer = filterContextItems(contextFilter{Filter: qfilter.Filter{Tags: map[string]string{"team": "payments"}}}, names[:3], byName)
+	if !strings.Contains(footer, "tag qualifiers are not available") {
+		t.Errorf("footer = %q", footer)
+	}
+	tagged := byName
+	tagged.tags = func(s string) map[string]string {
+		if strings.HasPrefix(s, "billing") {
+			return map[string]string{"team": "Payments"}
+		}
+		return map[string]string{"team": "core"}
+	}
+	selected, _ = filterContextItems(contextFilter{Filter: qfilter.Filter{Tags: map[string]string{"team": "payments"}}}, names, tagged)
+	if len(selected) != 2 {
+		t.Errorf("tag filter selected %d, want 2", len(selected))
+	}
+}
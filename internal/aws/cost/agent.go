@@ -0,0 +1,225 @@
+// Package cost answers AWS spend questions ("what did we spend on EC2 last
+// month", "top 5 services by cost this week") from Cost Explorer. The
+// question is turned into an aws ce get-cost-and-usage call with a time
+// range, grouping and service filter, compared with the previous period, and
+// rendered as a breakdown with a trend.
+package cost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/aws"
+)
+
+// maxPages bounds how many get-cost-and-usage pages one query reads
+const maxPages = 10
+
+// Agent answers cost questions with Cost Explorer
+type Agent struct {
+	run   aws.Runner
+	debug bool
+}
+
+// NewAgent creates a cost agent that calls Cost Explorer through run
+func NewAgent(run aws.Runner, debug bool) *Agent {
+	return &Agent{run: run, debug: debug}
+}
+
+// Result is the cost of one query, grouped and ordered by cost
+type Result struct {
+	Query    Query
+	Currency string
+	Total    float64
+	Groups   []GroupCost
+	// Periods holds each time bucket's total, oldest first
+	Periods []PeriodCost
+	// Previous is the total for Query.Range.Previous(); HasPrevious is false
+	// when it could not be fetched
+	Previous    float64
+	HasPrevious bool
+}
+
+// GroupCost is one group's spend in the current and previous period
+type GroupCost struct {
+	Name     string
+	Cost     float64
+	Previous float64
+}
+
+// PeriodCost is the total spend in one time bucket
+type PeriodCost struct {
+	Start time.Time
+	Cost  float64
+}
+
+// HandleQuery answers question and returns the formatted report
+func (a *Agent) HandleQuery(ctx context.Context, question string, now time.Time) (string, error) {
+	result, err := a.Fetch(ctx, ParseQuery(question, now))
+	if err != nil {
+		return "", err
+	}
+	return Format(result), nil
+}
+
+// Fetch runs q, then the same grouping over the previous period for the
+// trend. Failing to fetch the previous period only drops the comparison.
+func (a *Agent) Fetch(ctx context.Context, q Query) (*Result, error) {
+	if a.debug {
+		fmt.Printf("[aws-cost] %s: %s to %s, %s by %s\n", q.Range.Label,
+			q.Range.Start.Format(time.DateOnly), q.Range.End.Format(time.DateOnly), q.Granularity, q.GroupBy)
+	}
+	current, err := a.fetchPeriods(ctx, q, q.Range, q.Granularity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cost and usage: %w", err)
+	}
+
+	result := &Result{Query: q, Currency: "USD"}
+	groups := map[string]*GroupCost{}
+	for _, period := range current {
+		var periodTotal float64
+		for _, g := range period.Groups {
+			amount, unit := g.amount(q.Metric)
+			if unit != "" {
+				result.Currency = unit
+			}
+			name := strings.Join(g.Keys, " / ")
+			if groups[name] == nil {
+				groups[name] = &GroupCost{Name: name}
+			}
+			groups[name].Cost += amount
+			periodTotal += amount
+		}
+		result.Total += periodTotal
+		start, _ := time.Parse(time.DateOnly, period.TimePeriod.Start)
+		result.Periods = append(result.Periods, PeriodCost{Start: start, Cost: periodTotal})
+	}
+
+	previous, err := a.fetchPeriods(ctx, q, q.Range.Previous(), Monthly)
+	if err != nil {
+		if a.debug {
+			fmt.Printf("[aws-cost] previous period unavailable: %v\n", err)
+		}
+	} else {
+		result.HasPrevious = true
+		for _, period := range previous {
+			for _, g := range period.Groups {
+				amount, _ := g.amount(q.Metric)
+				result.Previous += amount
+				if gc := groups[strings.Join(g.Keys, " / ")]; gc != nil {
+					gc.Previous += amount
+				}
+			}
+		}
+	}
+
+	for _, g := range groups {
+		result.Groups = append(result.Groups, *g)
+	}
+	sort.Slice(result.Groups, func(i, j int) bool {
+		if result.Groups[i].Cost != result.Groups[j].Cost {
+			return result.Groups[i].Cost > result.Groups[j].Cost
+		}
+		return result.Groups[i].Name < result.Groups[j].Name
+	})
+	return result, nil
+}
+
+// fetchPeriods reads every page of get-cost-and-usage for r
+func (a *Agent) fetchPeriods(ctx context.Context, q Query, r Range, granularity string) ([]resultByTime, error) {
+	var periods []resultByTime
+	token := ""
+	for page := 0; page < maxPages; page++ {
+		out, err := a.run(ctx, q.Args(r, granularity, token))
+		if err != nil {
+			return nil, err
+		}
+		var resp costAndUsageOutput
+		if err := json.Unmarshal([]byte(out), &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse Cost Explorer output: %w", err)
+		}
+		periods = mergePeriods(periods, resp.ResultsByTime)
+		token = resp.NextPageToken
+		if token == "" {
+			break
+		}
+	}
+	return periods, nil
+}
+
+// mergePeriods appends a page of results; later pages continue the groups
+// of periods already seen
+func mergePeriods(periods, page []resultByTime) []resultByTime {
+	index := make(map[string]int, len(periods))
+	for i, p := range periods {
+		index[p.TimePeriod.Start] = i
+	}
+	for _, p := range page {
+		if i, ok := index[p.TimePeriod.Start]; ok {
+			periods[i].Groups = append(periods[i].Groups, p.Groups...)
+			continue
+		}
+		index[p.TimePeriod.Start] = len(periods)
+		periods = append(periods, p)
+	}
+	return periods
+}
+
+// costAndUsageOutput is the part of aws ce get-cost-and-usage output used here
+type costAndUsageOutput struct {
+	ResultsByTime []resultByTime `json:"ResultsByTime"`
+	NextPageToken string         `json:"NextPageToken"`
+}
+
+type resultByTime struct {
+	TimePeriod struct {
+		Start string `json:"Start"`
+		End   string `json:"End"`
+	} `json:"TimePeriod"`
+	Groups []group `json:"Groups"`
+}
+
+type group struct {
+	Keys    []string          `json:"Keys"`
+	Metrics map[string]metric `json:"Metrics"`
+}
+
+type metric struct {
+	Amount string `json:"Amount"`
+	Unit   string `json:"Unit"`
+}
+
+func (g group) amount(name string) (float64, string) {
+	m, ok := g.Metrics[name]
+	if !ok {
+		return 0, ""
+	}
+	amount, _ := strconv.ParseFloat(m.Amount, 64)
+	return amount, m.Unit
+}
+
+var (
+	spendRe   = regexp.MustCompile(`\b(?:spend|spent|spending|bill|billed|billing|invoice|cost explorer)\b`)
+	costRe    = regexp.MustCompile(`\bcosts?\b`)
+	savingsRe = regexp.MustCompile(`\b(?:save|saving|savings|reduce|cut|optimi[sz]e|optimi[sz]ation|cheaper|estimate|pricing|price)\b`)
+	amountRe  = regexp.MustCompile(`\b(?:how much|top \w+|breakdown|by service|by region|by account|per service|trend|last|this|past|today|yesterday|month|week|ytd|mtd)\b`)
+)
+
+// IsCostQuestion reports whether question asks what was spent, as opposed
+// to how to save money or what something would cost to run
+func IsCostQuestion(question string) bool {
+	q := strings.ToLower(question)
+	if savingsRe.MatchString(q) {
+		return false
+	}
+	if spendRe.MatchString(q) {
+		return true
+	}
+	return costRe.MatchString(q) && amountRe.MatchString(q)
+}
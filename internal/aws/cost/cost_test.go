@@ -0,0 +1,254 @@
+package cost
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Thursday 2026-10-15
+var testNow = time.Date(2026, time.October, 15, 9, 30, 0, 0, time.UTC)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		question   string
+		start, end string
+		label      string
+	}{
+		{"what did we spend on EC2 last month", "2026-09-01", "2026-10-01", "last month"},
+		{"top 5 services by cost this week", "2026-10-12", "2026-10-16", "this week"},
+		{"cost last week", "2026-10-05", "2026-10-12", "last week"},
+		{"spend today", "2026-10-15", "2026-10-16", "today"},
+		{"spend yesterday", "2026-10-14", "2026-10-15", "yesterday"},
+		{"spend over the past 30 days", "2026-09-15", "2026-10-15", "last 30 days"},
+		{"spend in the last two weeks", "2026-10-01", "2026-10-15", "last 2 weeks"},
+		{"spend for the last 3 months", "2026-07-01", "2026-10-01", "last 3 months"},
+		{"spend last quarter", "2026-07-01", "2026-10-01", "last quarter"},
+		{"spend this year", "2026-01-01", "2026-10-16", "this year"},
+		{"what did we spend in march", "2026-03-01", "2026-04-01", "March 2026"},
+		{"what did we spend in november", "2025-11-01", "2025-12-01", "November 2025"},
+		{"spend in october", "2026-10-01", "2026-10-16", "October 2026"},
+		{"bill for dec 2024", "2024-12-01", "2025-01-01", "December 2024"},
+		{"what may have driven our bill", "2026-10-01", "2026-10-16", "this month"},
+		{"what is our bill", "2026-10-01", "2026-10-16", "this month"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.question, func(t *testing.T) {
+			r := ParseRange(tt.question, testNow)
+			if !r.Start.Equal(date(tt.start)) || !r.End.Equal(date(tt.end)) || r.Label != tt.label {
+				t.Errorf("got %s..%s %q, want %s..%s %q", r.Start.Format(time.DateOnly), r.End.Format(time.DateOnly), r.Label, tt.start, tt.end, tt.label)
+			}
+		})
+	}
+}
+
+func TestRangePrevious(t *testing.T) {
+	tests := []struct {
+		name       string
+		r          Range
+		start, end string
+	}{
+		{"calendar month", Range{Start: date("2026-09-01"), End: date("2026-10-01")}, "2026-08-01", "2026-09-01"},
+		{"quarter", Range{Start: date("2026-07-01"), End: date("2026-10-01")}, "2026-04-01", "2026-07-01"},
+		{"month to date", Range{Start: date("2026-10-01"), End: date("2026-10-16")}, "2026-09-01", "2026-09-16"},
+		{"week", Range{Start: date("2026-10-05"), End: date("2026-10-12")}, "2026-09-28", "2026-10-05"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.r.Previous()
+			if !p.Start.Equal(date(tt.start)) || !p.End.Equal(date(tt.end)) {
+				t.Errorf("got %s..%s, want %s..%s", p.Start.Format(time.DateOnly), p.End.Format(time.DateOnly), tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	q := ParseQuery("what did we spend on EC2 last month", testNow)
+	if q.GroupBy != GroupUsageType || q.Granularity != Monthly || q.Metric != "UnblendedCost" {
+		t.Errorf("ec2 query = %+v", q)
+	}
+	if !slices.Equal(q.Services, serviceAliases["ec2"]) {
+		t.Errorf("services = %v", q.Services)
+	}
+
+	q = ParseQuery("top 5 services by cost this week", testNow)
+	if q.GroupBy != GroupService || q.TopN != 5 || q.Granularity != Daily || len(q.Services) != 0 {
+		t.Errorf("top 5 query = %+v", q)
+	}
+
+	q = ParseQuery("s3 and rds amortized cost by region over the last 3 months", testNow)
+	if q.GroupBy != GroupRegion || q.Metric != "AmortizedCost" || len(q.Services) != 2 {
+		t.Errorf("region query = %+v", q)
+	}
+
+	q = ParseQuery("daily spend trend last month", testNow)
+	if q.Granularity != Daily {
+		t.Errorf("trend granularity = %s, want DAILY", q.Granularity)
+	}
+}
+
+func TestQueryArgs(t *testing.T) {
+	q := ParseQuery("what did we spend on lambda last month", testNow)
+	args := strings.Join(q.Args(q.Range, q.Granularity, "tok"), " ")
+	for _, want := range []string{
+		"ce get-cost-and-usage",
+		"--time-period Start=2026-09-01,End=2026-10-01",
+		"--granularity MONTHLY",
+		"--metrics UnblendedCost",
+		"--group-by Type=DIMENSION,Key=USAGE_TYPE",
+		`--filter {"Dimensions":{"Key":"SERVICE","Values":["AWS Lambda"]}}`,
+		"--next-page-token tok",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args missing %q:\n%s", want, args)
+		}
+	}
+}
+
+const currentPage1 = `{
+  "ResultsByTime": [
+    {"TimePeriod": {"Start": "2026-10-12", "End": "2026-10-13"}, "Groups": [
+      {"Keys": ["Amazon Elastic Compute Cloud - Compute"], "Metrics": {"UnblendedCost": {"Amount": "100", "Unit": "USD"}}}
+    ]},
+    {"TimePeriod": {"Start": "2026-10-13", "End": "2026-10-14"}, "Groups": [
+      {"Keys": ["Amazon Elastic Compute Cloud - Compute"], "Metrics": {"UnblendedCost": {"Amount": "120", "Unit": "USD"}}}
+    ]}
+  ],
+  "NextPageToken": "page2"
+}`
+
+const currentPage2 = `{
+  "ResultsByTime": [
+    {"TimePeriod": {"Start": "2026-10-13", "End": "2026-10-14"}, "Groups": [
+      {"Keys": ["Amazon Simple Storage Service"], "Metrics": {"UnblendedCost": {"Amount": "30", "Unit": "USD"}}}
+    ]}
+  ]
+}`
+
+const previousPage = `{
+  "ResultsByTime": [
+    {"TimePeriod": {"Start": "2026-10-05", "End": "2026-10-09"}, "Groups": [
+      {"Keys": ["Amazon Elastic Compute Cloud - Compute"], "Metrics": {"UnblendedCost": {"Amount": "200", "Unit": "USD"}}},
+      {"Keys": ["Amazon Simple Storage Service"], "Metrics": {"UnblendedCost": {"Amount": "50", "Unit": "USD"}}}
+    ]}
+  ]
+}`
+
+func TestAgentFetchPagesAndComparesPreviousPeriod(t *testing.T) {
+	var calls [][]string
+	run := func(ctx context.Context, args []string) (string, error) {
+		calls = append(calls, args)
+		joined := strings.Join(args, " ")
+		switch {
+		case strings.Contains(joined, "Start=2026-10-12") && strings.Contains(joined, "page2"):
+			return currentPage2, nil
+		case strings.Contains(joined, "Start=2026-10-12"):
+			return currentPage1, nil
+		default:
+			return previousPage, nil
+		}
+	}
+
+	agent := NewAgent(run, false)
+	result, err := agent.Fetch(context.Background(), ParseQuery("top 5 services by cost this week", testNow))
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("expected 2 current pages and 1 previous call, got %d", len(calls))
+	}
+	if result.Total != 250 || result.Previous != 250 || !result.HasPrevious {
+		t.Errorf("totals = %v (previous %v)", result.Total, result.Previous)
+	}
+	if len(result.Groups) != 2 || result.Groups[0].Name != "Amazon Elastic Compute Cloud - Compute" || result.Groups[0].Cost != 220 || result.Groups[0].Previous != 200 {
+		t.Errorf("groups = %+v", result.Groups)
+	}
+	if len(result.Periods) != 2 || result.Periods[1].Cost != 150 {
+		t.Errorf("periods = %+v", result.Periods)
+	}
+
+	out := Format(result)
+	for _, want := range []string{
+		"AWS cost, this week (2026-10-12 to 2026-10-15)",
+		"Total: $250.00 USD  0.0% vs previous period ($250.00)",
+		"Top 2 by service:",
+		"▲ 10.0%",
+		"▼ 40.0%",
+		"Daily trend:",
+		"2026-10-13",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestAgentPreviousPeriodFailureKeepsResult(t *testing.T) {
+	run := func(ctx context.Context, args []string) (string, error) {
+		if strings.Contains(strings.Join(args, " "), "Start=2026-09-01") {
+			return `{"ResultsByTime": [{"TimePeriod": {"Start": "2026-09-01"}, "Groups": [{"Keys": ["AWS Lambda"], "Metrics": {"UnblendedCost": {"Amount": "12.5", "Unit": "USD"}}}]}]}`, nil
+		}
+		return "", errors.New("AccessDenied")
+	}
+	out, err := NewAgent(run, false).HandleQuery(context.Background(), "spend last month", testNow)
+	if err != nil {
+		t.Fatalf("HandleQuery: %v", err)
+	}
+	if !strings.Contains(out, "Total: $12.50 USD\n") || strings.Contains(out, "vs ") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestAgentCurrentPeriodFailure(t *testing.T) {
+	run := func(ctx context.Context, args []string) (string, error) {
+		return "", errors.New("AccessDenied")
+	}
+	if _, err := NewAgent(run, false).HandleQuery(context.Background(), "spend last month", testNow); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestIsCostQuestion(t *testing.T) {
+	tests := map[string]bool{
+		"what did we spend on EC2 last month":  true,
+		"top 5 services by cost this week":     true,
+		"how much does S3 cost us":             true,
+		"show the aws bill":                    true,
+		"how can we reduce our ec2 costs":      false,
+		"estimate the cost of a new cluster":   false,
+		"list running ec2 instances":           false,
+		"what is the cost allocation tag name": false,
+	}
+	for q, want := range tests {
+		if got := IsCostQuestion(q); got != want {
+			t.Errorf("IsCostQuestion(%q) = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func TestMoney(t *testing.T) {
+	tests := map[float64]string{
+		0:          "$0.00",
+		12.5:       "$12.50",
+		1234.567:   "$1,234.57",
+		1234567.89: "$1,234,567.89",
+		-42:        "-$42.00",
+	}
+	for in, want := range tests {
+		if got := money(in); got != want {
+			t.Errorf("money(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package cost
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// barWidth is the width of the longest bar in the trend chart
+const barWidth = 30
+
+var groupLabels = map[string]string{
+	GroupService:      "service",
+	GroupRegion:       "region",
+	GroupAccount:      "account",
+	GroupUsageType:    "usage type",
+	GroupInstanceType: "instance type",
+	GroupOperation:    "operation",
+}
+
+// Format renders a result as a total with its change, the top groups and a
+// per-period trend
+func Format(r *Result) string {
+	q := r.Query
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "AWS cost, %s (%s to %s)\n", q.Range.Label,
+		q.Range.Start.Format(time.DateOnly), q.Range.End.AddDate(0, 0, -1).Format(time.DateOnly))
+	if len(q.Aliases) > 0 {
+		fmt.Fprintf(&sb, "Filtered to: %s\n", strings.Join(q.Aliases, ", "))
+	}
+	fmt.Fprintf(&sb, "Total: %s %s", money(r.Total), r.Currency)
+	if r.HasPrevious {
+		fmt.Fprintf(&sb, "  %s vs %s (%s)", change(r.Total, r.Previous), q.Range.Previous().Label, money(r.Previous))
+	}
+	sb.WriteString("\n")
+
+	if len(r.Groups) == 0 {
+		sb.WriteString("\nNo spend recorded for this period.\n")
+		return sb.String()
+	}
+
+	shown := r.Groups
+	if len(shown) > q.TopN {
+		shown = shown[:q.TopN]
+	}
+	label := groupLabels[q.GroupBy]
+	fmt.Fprintf(&sb, "\nTop %d by %s:\n", len(shown), label)
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  %s\tCOST\tSHARE\tCHANGE\n", strings.ToUpper(label))
+	for _, g := range shown {
+		share := 0.0
+		if r.Total > 0 {
+			share = g.Cost / r.Total * 100
+		}
+		delta := "-"
+		if r.HasPrevious {
+			delta = change(g.Cost, g.Previous)
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%.1f%%\t%s\n", g.Name, money(g.Cost), share, delta)
+	}
+	w.Flush()
+	if rest := r.Groups[len(shown):]; len(rest) > 0 {
+		var restTotal float64
+		for _, g := range rest {
+			restTotal += g.Cost
+		}
+		fmt.Fprintf(&sb, "  ...and %d more totalling %s\n", len(rest), money(restTotal))
+	}
+
+	if len(r.Periods) > 1 {
+		layout, title := time.DateOnly, "Daily trend"
+		if q.Granularity == Monthly {
+			layout, title = "2006-01", "Monthly trend"
+		}
+		fmt.Fprintf(&sb, "\n%s:\n", title)
+		var peak float64
+		for _, p := range r.Periods {
+			peak = math.Max(peak, p.Cost)
+		}
+		for _, p := range r.Periods {
+			fmt.Fprintf(&sb, "  %s  %10s  %s\n", p.Start.Format(layout), money(p.Cost), bar(p.Cost, peak))
+		}
+	}
+	return sb.String()
+}
+
+// change describes the move from previous to current as a signed percentage
+func change(current, previous float64) string {
+	switch {
+	case previous == 0 && current == 0:
+		return "0.0%"
+	case previous == 0:
+		return "new"
+	}
+	pct := (current - previous) / previous * 100
+	switch {
+	case pct > 0.05:
+		return fmt.Sprintf("▲ %.1f%%", pct)
+	case pct < -0.05:
+		return fmt.Sprintf("▼ %.1f%%", -pct)
+	}
+	return "0.0%"
+}
+
+func bar(value, peak float64) string {
+	if peak <= 0 || value <= 0 {
+		return ""
+	}
+	n := int(math.Round(value / peak * barWidth))
+	if n == 0 {
+		n = 1
+	}
+	return strings.Repeat("█", n)
+}
+
+// money formats an amount as dollars with thousands separators
+func money(amount float64) string {
+	s := fmt.Sprintf("%.2f", math.Abs(amount))
+	whole, frac := s[:len(s)-3], s[len(s)-3:]
+	var out []byte
+	for i := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			out = append(out, ',')
+		}
+		out = append(out, whole[i])
+	}
+	sign := ""
+	if amount < 0 && s != "0.00" {
+		sign = "-"
+	}
+	return sign + "$" + string(out) + frac
+}
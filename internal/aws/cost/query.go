@@ -0,0 +1,175 @@
+package cost
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Cost Explorer dimensions a question can group by
+const (
+	GroupService      = "SERVICE"
+	GroupRegion       = "REGION"
+	GroupAccount      = "LINKED_ACCOUNT"
+	GroupUsageType    = "USAGE_TYPE"
+	GroupInstanceType = "INSTANCE_TYPE"
+	GroupOperation    = "OPERATION"
+)
+
+// Granularities passed to get-cost-and-usage
+const (
+	Daily   = "DAILY"
+	Monthly = "MONTHLY"
+)
+
+// defaultTopN caps the breakdown when the question does not ask for a count
+const defaultTopN = 10
+
+// Query is a question translated into Cost Explorer terms
+type Query struct {
+	Range       Range
+	Granularity string
+	GroupBy     string
+	Metric      string
+	// Services filters to these SERVICE dimension values; Aliases holds the
+	// names the user used for them
+	Services []string
+	Aliases  []string
+	TopN     int
+}
+
+// serviceAliases maps the names people use to Cost Explorer SERVICE values
+var serviceAliases = map[string][]string{
+	"ec2":             {"Amazon Elastic Compute Cloud - Compute", "EC2 - Other"},
+	"s3":              {"Amazon Simple Storage Service"},
+	"rds":             {"Amazon Relational Database Service"},
+	"lambda":          {"AWS Lambda"},
+	"dynamodb":        {"Amazon DynamoDB"},
+	"cloudfront":      {"Amazon CloudFront"},
+	"eks":             {"Amazon Elastic Container Service for Kubernetes"},
+	"ecs":             {"Amazon Elastic Container Service"},
+	"elasticache":     {"Amazon ElastiCache"},
+	"cloudwatch":      {"AmazonCloudWatch"},
+	"route53":         {"Amazon Route 53"},
+	"route 53":        {"Amazon Route 53"},
+	"vpc":             {"Amazon Virtual Private Cloud"},
+	"nat gateway":     {"Amazon Virtual Private Cloud"},
+	"elb":             {"Amazon Elastic Load Balancing"},
+	"load balancer":   {"Amazon Elastic Load Balancing"},
+	"sqs":             {"Amazon Simple Queue Service"},
+	"sns":             {"Amazon Simple Notification Service"},
+	"redshift":        {"Amazon Redshift"},
+	"opensearch":      {"Amazon OpenSearch Service"},
+	"bedrock":         {"Amazon Bedrock"},
+	"kms":             {"AWS Key Management Service"},
+	"secrets manager": {"AWS Secrets Manager"},
+}
+
+var (
+	aliasRes = func() map[string]*regexp.Regexp {
+		res := make(map[string]*regexp.Regexp, len(serviceAliases))
+		for alias := range serviceAliases {
+			res[alias] = regexp.MustCompile(`\b` + regexp.QuoteMeta(alias) + `s?\b`)
+		}
+		return res
+	}()
+	topNRe = regexp.MustCompile(`\btop\s+(\d+|[a-z]+)\b`)
+)
+
+// ParseQuery turns a question into a Cost Explorer query. Filtering to a
+// service breaks it down by usage type unless another grouping is asked for.
+func ParseQuery(question string, now time.Time) Query {
+	q := strings.ToLower(question)
+	query := Query{
+		Range:   ParseRange(question, now),
+		GroupBy: GroupService,
+		Metric:  "UnblendedCost",
+		TopN:    defaultTopN,
+	}
+
+	aliases := make([]string, 0, len(aliasRes))
+	for alias := range aliasRes {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	seen := map[string]bool{}
+	for _, alias := range aliases {
+		if !aliasRes[alias].MatchString(q) {
+			continue
+		}
+		query.Aliases = append(query.Aliases, alias)
+		for _, svc := range serviceAliases[alias] {
+			if !seen[svc] {
+				seen[svc] = true
+				query.Services = append(query.Services, svc)
+			}
+		}
+	}
+
+	switch {
+	case containsAny(q, "by region", "per region", "each region"):
+		query.GroupBy = GroupRegion
+	case containsAny(q, "by account", "per account", "each account", "linked account"):
+		query.GroupBy = GroupAccount
+	case containsAny(q, "by instance type", "per instance type"):
+		query.GroupBy = GroupInstanceType
+	case containsAny(q, "by operation", "per operation"):
+		query.GroupBy = GroupOperation
+	case containsAny(q, "by usage type", "per usage type", "usage type"):
+		query.GroupBy = GroupUsageType
+	case containsAny(q, "by service", "per service", "services"):
+		query.GroupBy = GroupService
+	case len(query.Services) > 0:
+		query.GroupBy = GroupUsageType
+	}
+
+	switch {
+	case strings.Contains(q, "amortized"):
+		query.Metric = "AmortizedCost"
+	case strings.Contains(q, "blended") && !strings.Contains(q, "unblended"):
+		query.Metric = "BlendedCost"
+	}
+
+	if m := topNRe.FindStringSubmatch(q); m != nil {
+		if n := parseCount(m[1]); n > 0 {
+			query.TopN = n
+		}
+	}
+
+	query.Granularity = Monthly
+	switch {
+	case containsAny(q, "monthly", "per month", "by month", "month over month", "each month"):
+	case containsAny(q, "daily", "per day", "by day", "each day", "day by day", "day over day"):
+		query.Granularity = Daily
+	case containsAny(q, "trend", "over time") && query.Range.Days() <= 62:
+		query.Granularity = Daily
+	case query.Range.Days() <= 14:
+		query.Granularity = Daily
+	}
+	return query
+}
+
+// Args returns the aws ce get-cost-and-usage arguments for the query over r
+// at granularity, continuing from pageToken when set
+func (q Query) Args(r Range, granularity, pageToken string) []string {
+	args := []string{
+		"ce", "get-cost-and-usage",
+		"--time-period", "Start=" + r.Start.Format(time.DateOnly) + ",End=" + r.End.Format(time.DateOnly),
+		"--granularity", granularity,
+		"--metrics", q.Metric,
+		"--group-by", "Type=DIMENSION,Key=" + q.GroupBy,
+		"--output", "json",
+	}
+	if len(q.Services) > 0 {
+		filter, _ := json.Marshal(map[string]any{
+			"Dimensions": map[string]any{"Key": "SERVICE", "Values": q.Services},
+		})
+		args = append(args, "--filter", string(filter))
+	}
+	if pageToken != "" {
+		args = append(args, "--next-page-token", pageToken)
+	}
+	return args
+}
@@ -0,0 +1,161 @@
+package cost
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Range is a Cost Explorer time period. End is exclusive, as the API expects.
+type Range struct {
+	Start time.Time
+	End   time.Time
+	Label string
+}
+
+// Days returns the number of days the range covers
+func (r Range) Days() int {
+	return int(r.End.Sub(r.Start).Hours() / 24)
+}
+
+// Previous returns the period the range is compared against: the same
+// number of whole months before it for calendar ranges, the same days of the
+// previous month for month-to-date, and the preceding days otherwise
+func (r Range) Previous() Range {
+	switch {
+	case r.Start.Day() == 1 && r.End.Day() == 1:
+		months := monthsBetween(r.Start, r.End)
+		return Range{Start: r.Start.AddDate(0, -months, 0), End: r.Start, Label: "previous period"}
+	case r.Start.Day() == 1 && r.Days() <= 31:
+		return Range{Start: r.Start.AddDate(0, -1, 0), End: r.End.AddDate(0, -1, 0), Label: "same days last month"}
+	default:
+		return Range{Start: r.Start.AddDate(0, 0, -r.Days()), End: r.Start, Label: "previous period"}
+	}
+}
+
+var (
+	lastNRe   = regexp.MustCompile(`\b(?:last|past|previous)\s+(\d+|[a-z]+)\s+(day|week|month)s?\b`)
+	monthRe   = regexp.MustCompile(`\b(january|february|march|april|may|june|july|august|september|october|november|december|jan|feb|mar|apr|jun|jul|aug|sep|sept|oct|nov|dec)\b(?:\s+(\d{4}))?`)
+	monthIn   = regexp.MustCompile(`\b(?:in|for|during|of)\s+$`)
+	numberMap = map[string]int{
+		"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+		"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	}
+	monthNames = map[string]time.Month{
+		"january": time.January, "jan": time.January, "february": time.February, "feb": time.February,
+		"march": time.March, "mar": time.March, "april": time.April, "apr": time.April, "may": time.May,
+		"june": time.June, "jun": time.June, "july": time.July, "jul": time.July,
+		"august": time.August, "aug": time.August, "september": time.September, "sep": time.September, "sept": time.September,
+		"october": time.October, "oct": time.October, "november": time.November, "nov": time.November,
+		"december": time.December, "dec": time.December,
+	}
+)
+
+// ParseRange reads the time period out of a question such as "last month",
+// "this week", "past 30 days" or "in march". Ranges ending today include
+// today. Questions without one default to month to date.
+func ParseRange(question string, now time.Time) Range {
+	q := strings.ToLower(question)
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	tomorrow := today.AddDate(0, 0, 1)
+	monthStart := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	quarterStart := time.Date(today.Year(), ((today.Month()-1)/3)*3+1, 1, 0, 0, 0, 0, time.UTC)
+	yearStart := time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	if m := lastNRe.FindStringSubmatch(q); m != nil {
+		if n := parseCount(m[1]); n > 0 {
+			switch m[2] {
+			case "day":
+				return Range{Start: today.AddDate(0, 0, -n), End: today, Label: "last " + strconv.Itoa(n) + " days"}
+			case "week":
+				return Range{Start: today.AddDate(0, 0, -7*n), End: today, Label: "last " + strconv.Itoa(n) + " weeks"}
+			case "month":
+				return Range{Start: monthStart.AddDate(0, -n, 0), End: monthStart, Label: "last " + strconv.Itoa(n) + " months"}
+			}
+		}
+	}
+
+	switch {
+	case strings.Contains(q, "yesterday"):
+		return Range{Start: today.AddDate(0, 0, -1), End: today, Label: "yesterday"}
+	case strings.Contains(q, "today"):
+		return Range{Start: today, End: tomorrow, Label: "today"}
+	case containsAny(q, "last week", "previous week", "past week"):
+		return Range{Start: weekStart.AddDate(0, 0, -7), End: weekStart, Label: "last week"}
+	case containsAny(q, "this week", "week to date", "wtd"):
+		return Range{Start: weekStart, End: tomorrow, Label: "this week"}
+	case containsAny(q, "last month", "previous month", "past month"):
+		return Range{Start: monthStart.AddDate(0, -1, 0), End: monthStart, Label: "last month"}
+	case containsAny(q, "last quarter", "previous quarter"):
+		return Range{Start: quarterStart.AddDate(0, -3, 0), End: quarterStart, Label: "last quarter"}
+	case containsAny(q, "this quarter", "quarter to date", "qtd"):
+		return Range{Start: quarterStart, End: tomorrow, Label: "this quarter"}
+	case containsAny(q, "last year", "previous year"):
+		return Range{Start: yearStart.AddDate(-1, 0, 0), End: yearStart, Label: "last year"}
+	case containsAny(q, "this year", "year to date", "ytd"):
+		return Range{Start: yearStart, End: tomorrow, Label: "this year"}
+	}
+
+	if r, ok := parseMonth(q, today); ok {
+		return r
+	}
+	return Range{Start: monthStart, End: tomorrow, Label: "this month"}
+}
+
+// parseMonth matches a named month, optionally with a year. Without a year
+// it is the most recent such month; the current month runs to today.
+func parseMonth(q string, today time.Time) (Range, bool) {
+	for _, idx := range monthRe.FindAllStringSubmatchIndex(q, -1) {
+		name := q[idx[2]:idx[3]]
+		// "may" and "mar" are common words; only trust them after a
+		// preposition or with a year
+		hasYear := idx[4] >= 0
+		if (name == "may" || name == "mar") && !hasYear && !monthIn.MatchString(q[:idx[0]]) {
+			continue
+		}
+		month := monthNames[name]
+		year := today.Year()
+		if hasYear {
+			year, _ = strconv.Atoi(q[idx[4]:idx[5]])
+		} else if month > today.Month() {
+			year--
+		}
+		start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		end := start.AddDate(0, 1, 0)
+		if tomorrow := today.AddDate(0, 0, 1); end.After(tomorrow) {
+			end = tomorrow
+		}
+		if !start.Before(end) {
+			continue
+		}
+		return Range{Start: start, End: end, Label: start.Format("January 2006")}, true
+	}
+	return Range{}, false
+}
+
+func parseCount(s string) int {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return numberMap[s]
+}
+
+func monthsBetween(start, end time.Time) int {
+	months := (end.Year()-start.Year())*12 + int(end.Month()) - int(start.Month())
+	if months < 1 {
+		return 1
+	}
+	return months
+}
+
+func containsAny(s string, phrases ...string) bool {
+	for _, p := range phrases {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
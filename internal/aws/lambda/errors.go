@@ -0,0 +1,24 @@
+package lambda
+
+import (
+	"context"
+	"time"
+)
+
+// ErrorsQuery is the Logs Insights query for a function's failed
+// invocations: errors and exceptions its code logs, plus the timeouts and
+// runtime crashes Lambda itself reports
+const ErrorsQuery = "fields @timestamp, @requestId, @message\n" +
+	"| filter @message like /(?i)(error|exception|task timed out|runtime exited|fail)/\n" +
+	"| sort @timestamp desc\n" +
+	"| limit 50"
+
+// Activity loads f's invocations, errors and throttles over window
+func (a *Agent) Activity(ctx context.Context, f *Function, window time.Duration, now time.Time) error {
+	functions := []Function{*f}
+	if err := a.loadMetrics(ctx, functions, window, now); err != nil {
+		return err
+	}
+	*f = functions[0]
+	return nil
+}
@@ -0,0 +1,104 @@
+package lambda
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// highErrorRate is the error rate, in percent, above which a function is
+// called out
+const highErrorRate = 5
+
+// Format renders the functions as a table with their activity over the
+// window, followed by the functions failing most often
+func (l *FunctionList) Format() string {
+	if len(l.Functions) == 0 {
+		return "No Lambda functions found.\n"
+	}
+	var sb strings.Builder
+	window := windowLabel(l)
+	fmt.Fprintf(&sb, "Lambda functions (%d):\n", len(l.Functions))
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  FUNCTION\tRUNTIME\tMEMORY\tTIMEOUT\tINVOCATIONS (%s)\tERRORS\tERROR RATE\tTHROTTLES\tLAST MODIFIED\n", window)
+	var failing []Function
+	for _, f := range l.Functions {
+		invocations, errs, rate, throttles := "-", "-", "-", "-"
+		if f.HasMetrics {
+			invocations = fmt.Sprintf("%.0f", f.Invocations)
+			errs = fmt.Sprintf("%.0f", f.Errors)
+			throttles = fmt.Sprintf("%.0f", f.Throttles)
+			if f.Invocations > 0 {
+				rate = fmt.Sprintf("%.1f%%", f.ErrorRate())
+			}
+			if f.Errors > 0 && f.ErrorRate() >= highErrorRate {
+				failing = append(failing, f)
+			}
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%d MB\t%ds\t%s\t%s\t%s\t%s\t%s\n", f.Name, runtimeLabel(f), f.MemoryMB, f.TimeoutSec,
+			invocations, errs, rate, throttles, lastModified(f.LastModified))
+	}
+	tw.Flush()
+
+	if len(failing) > 0 {
+		sort.SliceStable(failing, func(i, j int) bool { return failing[i].ErrorRate() > failing[j].ErrorRate() })
+		fmt.Fprintf(&sb, "\nFailing %d%% or more of invocations:\n", highErrorRate)
+		for _, f := range failing {
+			fmt.Fprintf(&sb, "  - %s: %.0f of %.0f failed (%.1f%%)\n", f.Name, f.Errors, f.Invocations, f.ErrorRate())
+		}
+		fmt.Fprintf(&sb, "\nSee the errors with:\n  clanker agents aws-lambda \"recent errors in lambda %s\"\n", failing[0].Name)
+	}
+	if l.MetricsErr != nil {
+		fmt.Fprintf(&sb, "\nMetrics unavailable: %v\n", l.MetricsErr)
+	}
+	return sb.String()
+}
+
+// Summary is a one-line description of the function's configuration and,
+// when loaded, its activity
+func (f Function) Summary() string {
+	s := fmt.Sprintf("Lambda %s: %s, %d MB, %ds timeout, last modified %s", f.Name, runtimeLabel(f), f.MemoryMB, f.TimeoutSec, lastModified(f.LastModified))
+	if f.HasMetrics {
+		s += fmt.Sprintf("; %.0f invocations, %.0f errors", f.Invocations, f.Errors)
+	}
+	return s
+}
+
+func runtimeLabel(f Function) string {
+	label := f.Runtime
+	if f.PackageType == PackageImage {
+		label = "container image"
+	}
+	if label == "" {
+		label = "-"
+	}
+	if f.Architecture != "" {
+		label += " (" + f.Architecture + ")"
+	}
+	return label
+}
+
+// lastModified trims Lambda's 2026-10-15T09:30:00.000+0000 timestamps to
+// the minute
+func lastModified(ts string) string {
+	if len(ts) >= 16 {
+		return strings.Replace(ts[:16], "T", " ", 1)
+	}
+	if ts == "" {
+		return "-"
+	}
+	return ts
+}
+
+func windowLabel(l *FunctionList) string {
+	h := int(l.Window.Hours())
+	switch {
+	case h > 0 && h%24 == 0:
+		return fmt.Sprintf("%dd", h/24)
+	case h > 0 && l.Window == time.Duration(h)*time.Hour:
+		return fmt.Sprintf("%dh", h)
+	}
+	return l.Window.String()
+}
@@ -0,0 +1,166 @@
+// Package lambda answers operational questions about AWS Lambda functions.
+// It lists functions with their runtime, memory and recent error rate,
+// points error questions at the function's log group, and turns environment
+// variable changes and code deploys from a local zip or an ECR image into
+// maker plans to review and apply.
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/aws"
+)
+
+// Agent reads Lambda state and builds plans that change it
+type Agent struct {
+	run   aws.Runner
+	debug bool
+}
+
+// NewAgent creates a Lambda agent that calls AWS through run
+func NewAgent(run aws.Runner, debug bool) *Agent {
+	return &Agent{run: run, debug: debug}
+}
+
+// DefaultWindow is how far back listings total invocations and errors
+const DefaultWindow = 24 * time.Hour
+
+// Package types a function's code can be deployed as
+const (
+	PackageZip   = "Zip"
+	PackageImage = "Image"
+)
+
+// Function is a Lambda function's configuration with its recent activity
+type Function struct {
+	Name         string
+	Runtime      string
+	MemoryMB     int
+	TimeoutSec   int
+	PackageType  string
+	Architecture string
+	LastModified string
+	Version      string
+	LogGroup     string
+	Environment  map[string]string
+	// Invocations, Errors and Throttles are totals over the metrics window;
+	// HasMetrics is false when CloudWatch could not be read
+	Invocations float64
+	Errors      float64
+	Throttles   float64
+	HasMetrics  bool
+}
+
+// ErrorRate is the share of invocations that failed, in percent
+func (f Function) ErrorRate() float64 {
+	if f.Invocations == 0 {
+		return 0
+	}
+	return f.Errors / f.Invocations * 100
+}
+
+// functionConfig is the configuration list-functions and
+// get-function-configuration return
+type functionConfig struct {
+	FunctionName  string
+	Runtime       string
+	MemorySize    int
+	Timeout       int
+	PackageType   string
+	Architectures []string
+	LastModified  string
+	Version       string
+	Environment   struct {
+		Variables map[string]string
+	}
+	LoggingConfig struct {
+		LogGroup string
+	}
+}
+
+func (c functionConfig) function() Function {
+	f := Function{
+		Name:         c.FunctionName,
+		Runtime:      c.Runtime,
+		MemoryMB:     c.MemorySize,
+		TimeoutSec:   c.Timeout,
+		PackageType:  c.PackageType,
+		LastModified: c.LastModified,
+		Version:      c.Version,
+		LogGroup:     c.LoggingConfig.LogGroup,
+		Environment:  c.Environment.Variables,
+	}
+	if f.PackageType == "" {
+		f.PackageType = PackageZip
+	}
+	if len(c.Architectures) > 0 {
+		f.Architecture = c.Architectures[0]
+	}
+	if f.LogGroup == "" {
+		f.LogGroup = "/aws/lambda/" + f.Name
+	}
+	if f.Environment == nil {
+		f.Environment = map[string]string{}
+	}
+	return f
+}
+
+// runJSON runs an aws command with JSON output and decodes it into out
+func (a *Agent) runJSON(ctx context.Context, out any, args ...string) error {
+	raw, err := a.run(ctx, append(args, "--output", "json"))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("failed to parse %s %s output: %w", args[0], args[1], err)
+	}
+	return nil
+}
+
+// Function returns the configuration of the function named name
+func (a *Agent) Function(ctx context.Context, name string) (Function, error) {
+	var cfg functionConfig
+	if err := a.runJSON(ctx, &cfg, "lambda", "get-function-configuration", "--function-name", name); err != nil {
+		if strings.Contains(err.Error(), "ResourceNotFoundException") {
+			return Function{}, fmt.Errorf("no Lambda function named %s", name)
+		}
+		return Function{}, fmt.Errorf("failed to read function %s: %w", name, err)
+	}
+	return cfg.function(), nil
+}
+
+// FunctionList is every function in the region with its activity over the
+// metrics window
+type FunctionList struct {
+	Functions []Function
+	Window    time.Duration
+	// MetricsErr is set when CloudWatch could not be read; the functions are
+	// still listed
+	MetricsErr error
+}
+
+// List returns every function in the region with its invocations, errors
+// and throttles over window
+func (a *Agent) List(ctx context.Context, window time.Duration, now time.Time) (*FunctionList, error) {
+	var resp struct {
+		Functions []functionConfig
+	}
+	if err := a.runJSON(ctx, &resp, "lambda", "list-functions"); err != nil {
+		return nil, fmt.Errorf("failed to list functions: %w", err)
+	}
+	list := &FunctionList{Window: window}
+	for _, c := range resp.Functions {
+		list.Functions = append(list.Functions, c.function())
+	}
+	sort.Slice(list.Functions, func(i, j int) bool { return list.Functions[i].Name < list.Functions[j].Name })
+	if a.debug {
+		fmt.Printf("[aws-lambda] found %d functions\n", len(list.Functions))
+	}
+	list.MetricsErr = a.loadMetrics(ctx, list.Functions, window, now)
+	return list, nil
+}
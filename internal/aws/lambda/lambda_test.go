@@ -0,0 +1,255 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+const testImage = "123456789012.dkr.ecr.us-east-1.amazonaws.com/checkout:v42"
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     Request
+	}{
+		{"list lambda functions with their error rates", Request{Op: List}},
+		{"show recent errors in lambda checkout", Request{Op: Errors, Function: "checkout"}},
+		{"why is the payments lambda failing", Request{Op: Errors, Function: "payments"}},
+		{
+			"set env LOG_LEVEL=debug and API_URL=\"https://api.example.com\" on lambda checkout",
+			Request{Op: SetEnv, Function: "checkout", Set: map[string]string{"LOG_LEVEL": "debug", "API_URL": "https://api.example.com"}},
+		},
+		{
+			"remove the env var FEATURE_X from the checkout lambda",
+			Request{Op: SetEnv, Function: "checkout", Set: map[string]string{}, Unset: []string{"FEATURE_X"}},
+		},
+		{"deploy ./dist/checkout.zip to lambda checkout", Request{Op: Publish, Function: "checkout", ZipFile: "./dist/checkout.zip"}},
+		{"publish " + testImage + " to the checkout lambda", Request{Op: Publish, Function: "checkout", ImageURI: testImage}},
+		{"publish a new version of lambda checkout", Request{Op: Publish, Function: "checkout"}},
+	}
+	for _, tt := range tests {
+		if got := ParseRequest(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestIsLambdaQuestion(t *testing.T) {
+	for _, q := range []string{
+		"list lambda functions with their error rates",
+		"which lambdas use the most memory",
+		"set env LOG_LEVEL=debug on lambda checkout",
+		"deploy build.zip to lambda checkout",
+	} {
+		if !IsLambdaQuestion(q) {
+			t.Errorf("IsLambdaQuestion(%q) = false", q)
+		}
+	}
+	for _, q := range []string{
+		"create a lambda named checkout",
+		"how much do my lambdas cost",
+		"list lambda layers",
+		"list ec2 instances",
+	} {
+		if IsLambdaQuestion(q) {
+			t.Errorf("IsLambdaQuestion(%q) = true", q)
+		}
+	}
+}
+
+// fakeLambda answers the aws calls the agent makes
+type fakeLambda struct {
+	calls [][]string
+}
+
+func (f *fakeLambda) run(ctx context.Context, args []string) (string, error) {
+	f.calls = append(f.calls, args)
+	switch strings.Join(args[:2], " ") {
+	case "lambda list-functions":
+		return `{"Functions": [
+			{"FunctionName": "payments", "Runtime": "python3.12", "MemorySize": 512, "Timeout": 30,
+			 "Architectures": ["arm64"], "LastModified": "2026-10-01T08:00:00.000+0000"},
+			{"FunctionName": "checkout", "Runtime": "nodejs20.x", "MemorySize": 256, "Timeout": 10,
+			 "Architectures": ["x86_64"], "LastModified": "2026-10-14T09:30:00.000+0000"}
+		]}`, nil
+	case "lambda get-function-configuration":
+		if args[3] == "render" {
+			return `{"FunctionName": "render", "PackageType": "Image", "MemorySize": 2048, "Timeout": 60}`, nil
+		}
+		if args[3] != "checkout" {
+			return "", errors.New("An error occurred (ResourceNotFoundException) when calling the GetFunctionConfiguration operation")
+		}
+		return `{"FunctionName": "checkout", "Runtime": "nodejs20.x", "PackageType": "Zip", "MemorySize": 256, "Timeout": 10,
+			"Environment": {"Variables": {"LOG_LEVEL": "info", "TABLE": "orders"}},
+			"LoggingConfig": {"LogGroup": "/custom/checkout"}}`, nil
+	case "cloudwatch get-metric-data":
+		var queries []struct {
+			ID string `json:"Id"`
+		}
+		if err := json.Unmarshal([]byte(args[slices.Index(args, "--metric-data-queries")+1]), &queries); err != nil {
+			return "", err
+		}
+		// list order is checkout (f0), payments (f1)
+		values := map[string]string{"f0_0": "[600, 400]", "f0_1": "[80]", "f1_0": "[50]", "f1_1": "[]", "f1_2": "[3]"}
+		var results []string
+		for _, q := range queries {
+			v, ok := values[q.ID]
+			if !ok {
+				v = "[]"
+			}
+			results = append(results, `{"Id": "`+q.ID+`", "Values": `+v+`}`)
+		}
+		return `{"MetricDataResults": [` + strings.Join(results, ",") + `]}`, nil
+	}
+	return "", errors.New("unexpected call: " + strings.Join(args, " "))
+}
+
+func TestListWithMetrics(t *testing.T) {
+	aws := &fakeLambda{}
+	list, err := NewAgent(aws.run, false).List(context.Background(), 24*time.Hour, testNow)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	checkout := list.Functions[0]
+	if checkout.Name != "checkout" || checkout.Invocations != 1000 || checkout.Errors != 80 || checkout.ErrorRate() != 8 {
+		t.Errorf("checkout = %+v", checkout)
+	}
+	if metrics := aws.calls[1]; !strings.Contains(strings.Join(metrics, " "), `"Period":86400`) {
+		t.Errorf("get-metric-data args = %v", metrics)
+	}
+
+	out := list.Format()
+	for _, want := range []string{
+		"Lambda functions (2):",
+		"INVOCATIONS (1d)",
+		"nodejs20.x (x86_64)",
+		"256 MB",
+		"8.0%",
+		"2026-10-14 09:30",
+		"Failing 5% or more of invocations:\n  - checkout: 80 of 1000 failed (8.0%)",
+		`clanker agents aws-lambda "recent errors in lambda checkout"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestListWithoutMetrics(t *testing.T) {
+	run := func(ctx context.Context, args []string) (string, error) {
+		if args[0] == "cloudwatch" {
+			return "", errors.New("AccessDenied")
+		}
+		return (&fakeLambda{}).run(ctx, args)
+	}
+	list, err := NewAgent(run, false).List(context.Background(), time.Hour, testNow)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	out := list.Format()
+	if !strings.Contains(out, "INVOCATIONS (1h)") || !strings.Contains(out, "Metrics unavailable: failed to read Lambda metrics: AccessDenied") {
+		t.Errorf("output:\n%s", out)
+	}
+}
+
+func TestFunction(t *testing.T) {
+	agent := NewAgent((&fakeLambda{}).run, false)
+	f, err := agent.Function(context.Background(), "checkout")
+	if err != nil {
+		t.Fatalf("Function: %v", err)
+	}
+	if f.LogGroup != "/custom/checkout" || f.Environment["TABLE"] != "orders" {
+		t.Errorf("function = %+v", f)
+	}
+	if _, err := agent.Function(context.Background(), "nope"); err == nil || err.Error() != "no Lambda function named nope" {
+		t.Errorf("missing function err = %v", err)
+	}
+}
+
+func TestEnvPlan(t *testing.T) {
+	agent := NewAgent((&fakeLambda{}).run, false)
+	req := Request{Op: SetEnv, Function: "checkout", Set: map[string]string{"LOG_LEVEL": "debug", "NEW": "1"}, Unset: []string{"TABLE"}}
+	plan, err := agent.Plan(context.Background(), req, "q", testNow)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if plan.Summary != "Update environment of Lambda checkout: change LOG_LEVEL, add NEW, remove TABLE" {
+		t.Errorf("summary = %q", plan.Summary)
+	}
+	args := plan.Commands[0].Args
+	var env struct{ Variables map[string]string }
+	if err := json.Unmarshal([]byte(args[len(args)-1]), &env); err != nil {
+		t.Fatalf("environment arg: %v", err)
+	}
+	if !reflect.DeepEqual(env.Variables, map[string]string{"LOG_LEVEL": "debug", "NEW": "1"}) {
+		t.Errorf("variables = %v", env.Variables)
+	}
+
+	for _, tt := range []struct {
+		req  Request
+		want string
+	}{
+		{Request{Op: SetEnv, Function: "checkout", Set: map[string]string{"TABLE": "orders"}}, "already has those environment variables"},
+		{Request{Op: SetEnv, Function: "checkout", Unset: []string{"MISSING"}}, "has no variable MISSING"},
+		{Request{Op: SetEnv, Set: map[string]string{"A": "1"}}, "name the Lambda function"},
+	} {
+		if _, err := agent.Plan(context.Background(), tt.req, "q", testNow); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Plan(%+v) err = %v, want %q", tt.req, err, tt.want)
+		}
+	}
+}
+
+func TestPublishPlan(t *testing.T) {
+	agent := NewAgent((&fakeLambda{}).run, false)
+	zip := filepath.Join(t.TempDir(), "checkout.zip")
+	if err := os.WriteFile(zip, []byte("PK"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := agent.Plan(context.Background(), Request{Op: Publish, Function: "checkout", ZipFile: zip}, "q", testNow)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	want := []string{"lambda", "update-function-code", "--function-name", "checkout", "--zip-file", "fileb://" + zip, "--publish"}
+	if !slices.Equal(plan.Commands[0].Args, want) || plan.Commands[1].Args[1] != "wait" {
+		t.Errorf("commands = %v", plan.Commands)
+	}
+
+	plan, err = agent.Plan(context.Background(), Request{Op: Publish, Function: "render", ImageURI: testImage}, "q", testNow)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if !slices.Contains(plan.Commands[0].Args, testImage) {
+		t.Errorf("image commands = %v", plan.Commands)
+	}
+
+	plan, err = agent.Plan(context.Background(), Request{Op: Publish, Function: "checkout"}, "q", testNow)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(plan.Commands) != 1 || plan.Commands[0].Args[1] != "publish-version" {
+		t.Errorf("publish-version commands = %v", plan.Commands)
+	}
+
+	for _, tt := range []struct {
+		req  Request
+		want string
+	}{
+		{Request{Op: Publish, Function: "checkout", ImageURI: testImage}, "deployed as a zip package"},
+		{Request{Op: Publish, Function: "render", ZipFile: zip}, "deployed as a container image"},
+		{Request{Op: Publish, Function: "checkout", ZipFile: filepath.Join(t.TempDir(), "missing.zip")}, "cannot read"},
+	} {
+		if _, err := agent.Plan(context.Background(), tt.req, "q", testNow); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Plan(%+v) err = %v, want %q", tt.req, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// maxMetricQueries is how many queries one get-metric-data call accepts
+const maxMetricQueries = 500
+
+// metricNames are the per-function totals the listing reports
+var metricNames = []string{"Invocations", "Errors", "Throttles"}
+
+// loadMetrics sums each function's invocations, errors and throttles over
+// window
+func (a *Agent) loadMetrics(ctx context.Context, functions []Function, window time.Duration, now time.Time) error {
+	if len(functions) == 0 {
+		return nil
+	}
+	type dimension struct {
+		Name  string `json:"Name"`
+		Value string `json:"Value"`
+	}
+	type query struct {
+		ID         string `json:"Id"`
+		MetricStat struct {
+			Metric struct {
+				Namespace  string      `json:"Namespace"`
+				MetricName string      `json:"MetricName"`
+				Dimensions []dimension `json:"Dimensions"`
+			} `json:"Metric"`
+			Period int    `json:"Period"`
+			Stat   string `json:"Stat"`
+		} `json:"MetricStat"`
+		ReturnData bool `json:"ReturnData"`
+	}
+	// Periods are whole minutes
+	period := int(window.Round(time.Minute).Seconds())
+	period = max(period, 60)
+	var queries []query
+	for i, f := range functions {
+		for j, name := range metricNames {
+			var q query
+			q.ID = fmt.Sprintf("f%d_%d", i, j)
+			q.MetricStat.Metric.Namespace = "AWS/Lambda"
+			q.MetricStat.Metric.MetricName = name
+			q.MetricStat.Metric.Dimensions = []dimension{{Name: "FunctionName", Value: f.Name}}
+			// One period covering the window makes each result a single total
+			q.MetricStat.Period = period
+			q.MetricStat.Stat = "Sum"
+			q.ReturnData = true
+			queries = append(queries, q)
+		}
+	}
+
+	start := now.Add(-window).UTC().Format(time.RFC3339)
+	end := now.UTC().Format(time.RFC3339)
+	totals := map[string]float64{}
+	for offset := 0; offset < len(queries); offset += maxMetricQueries {
+		batch, _ := json.Marshal(queries[offset:min(offset+maxMetricQueries, len(queries))])
+		var resp struct {
+			MetricDataResults []struct {
+				ID     string    `json:"Id"`
+				Values []float64 `json:"Values"`
+			} `json:"MetricDataResults"`
+		}
+		if err := a.runJSON(ctx, &resp, "cloudwatch", "get-metric-data",
+			"--metric-data-queries", string(batch), "--start-time", start, "--end-time", end); err != nil {
+			return fmt.Errorf("failed to read Lambda metrics: %w", err)
+		}
+		for _, r := range resp.MetricDataResults {
+			for _, v := range r.Values {
+				totals[r.ID] += v
+			}
+		}
+	}
+	for i := range functions {
+		f := &functions[i]
+		f.Invocations = totals[fmt.Sprintf("f%d_0", i)]
+		f.Errors = totals[fmt.Sprintf("f%d_1", i)]
+		f.Throttles = totals[fmt.Sprintf("f%d_2", i)]
+		f.HasMetrics = true
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// maxZipBytes is the largest zip update-function-code accepts directly;
+// larger packages go through S3
+const maxZipBytes = 50 << 20
+
+// Plan builds the maker plan for an environment change or a deploy of the
+// function req names. The plan is returned for review; nothing is changed.
+func (a *Agent) Plan(ctx context.Context, req Request, question string, now time.Time) (*maker.Plan, error) {
+	if req.Function == "" {
+		return nil, fmt.Errorf("name the Lambda function, e.g. \"lambda checkout\"")
+	}
+	f, err := a.Function(ctx, req.Function)
+	if err != nil {
+		return nil, err
+	}
+	switch req.Op {
+	case SetEnv:
+		return EnvPlan(f, req, question, now)
+	case Publish:
+		return PublishPlan(f, req, question, now)
+	}
+	return nil, fmt.Errorf("nothing to plan for a listing")
+}
+
+func newPlan(question string, now time.Time) *maker.Plan {
+	return &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "aws",
+		Question:  question,
+	}
+}
+
+// EnvPlan returns a plan that sets and removes environment variables on f.
+// update-function-configuration replaces the whole set, so the plan carries
+// every variable f keeps.
+func EnvPlan(f Function, req Request, question string, now time.Time) (*maker.Plan, error) {
+	vars := make(map[string]string, len(f.Environment)+len(req.Set))
+	for k, v := range f.Environment {
+		vars[k] = v
+	}
+	var changes []string
+	for _, k := range sortedKeys(req.Set) {
+		switch old, ok := vars[k]; {
+		case !ok:
+			changes = append(changes, "add "+k)
+		case old != req.Set[k]:
+			changes = append(changes, "change "+k)
+		}
+		vars[k] = req.Set[k]
+	}
+	var missing []string
+	for _, k := range req.Unset {
+		if _, ok := vars[k]; !ok {
+			missing = append(missing, k)
+			continue
+		}
+		delete(vars, k)
+		changes = append(changes, "remove "+k)
+	}
+	if len(changes) == 0 {
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("%s has no variable %s", f.Name, strings.Join(missing, " or "))
+		}
+		return nil, fmt.Errorf("%s already has those environment variables", f.Name)
+	}
+
+	env, _ := json.Marshal(map[string]map[string]string{"Variables": vars})
+	plan := newPlan(question, now)
+	plan.Summary = fmt.Sprintf("Update environment of Lambda %s: %s", f.Name, strings.Join(changes, ", "))
+	plan.Commands = []maker.Command{
+		{Args: []string{"lambda", "update-function-configuration", "--function-name", f.Name, "--environment", string(env)},
+			Reason: strings.Join(changes, ", ")},
+		{Args: []string{"lambda", "wait", "function-updated", "--function-name", f.Name},
+			Reason: "Wait for the configuration update to finish"},
+	}
+	plan.Notes = append(plan.Notes,
+		"The plan lists every variable the function keeps, since the update replaces the whole set; treat it as sensitive if they hold secrets",
+		"New invocations pick up the change; published versions keep their own environment")
+	if len(missing) > 0 {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("%s had no variable %s", f.Name, strings.Join(missing, " or ")))
+	}
+	return plan, nil
+}
+
+// PublishPlan returns a plan that deploys new code to f from a local zip or
+// an ECR image and publishes it as a new version, or publishes the current
+// code when req names neither
+func PublishPlan(f Function, req Request, question string, now time.Time) (*maker.Plan, error) {
+	plan := newPlan(question, now)
+	var update []string
+	switch {
+	case req.ImageURI != "":
+		if f.PackageType != PackageImage {
+			return nil, fmt.Errorf("%s is deployed as a zip package, not a container image; deploy a .zip instead", f.Name)
+		}
+		update = []string{"lambda", "update-function-code", "--function-name", f.Name, "--image-uri", req.ImageURI, "--publish"}
+		plan.Summary = fmt.Sprintf("Deploy %s to Lambda %s and publish a new version", req.ImageURI, f.Name)
+	case req.ZipFile != "":
+		if f.PackageType == PackageImage {
+			return nil, fmt.Errorf("%s is deployed as a container image; push an image to ECR and deploy its URI instead", f.Name)
+		}
+		path, err := zipPath(req.ZipFile)
+		if err != nil {
+			return nil, err
+		}
+		update = []string{"lambda", "update-function-code", "--function-name", f.Name, "--zip-file", "fileb://" + path, "--publish"}
+		plan.Summary = fmt.Sprintf("Deploy %s to Lambda %s and publish a new version", path, f.Name)
+		plan.Notes = append(plan.Notes, "The zip is read from "+path+" when the plan is applied; keep it there until then")
+	default:
+		plan.Summary = fmt.Sprintf("Publish the current code and configuration of Lambda %s as a new version", f.Name)
+		plan.Commands = []maker.Command{
+			{Args: []string{"lambda", "publish-version", "--function-name", f.Name, "--description", "Published by clanker"},
+				Reason: "Publish $LATEST as a new version"},
+		}
+		return plan, nil
+	}
+
+	plan.Commands = []maker.Command{
+		{Args: update, Reason: "Upload the new code and publish it as a version"},
+		{Args: []string{"lambda", "wait", "function-updated", "--function-name", f.Name},
+			Reason: "Wait for the code update to finish"},
+	}
+	plan.Notes = append(plan.Notes, "Aliases keep pointing at their current version; move one with lambda update-alias once the new version is verified")
+	return plan, nil
+}
+
+// zipPath resolves a zip named in a question to an absolute path and checks
+// it can be uploaded directly
+func zipPath(name string) (string, error) {
+	if rest, ok := strings.CutPrefix(name, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		name = filepath.Join(home, rest)
+	}
+	path, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s: %w", name, err)
+	}
+	if info.Size() > maxZipBytes {
+		return "", fmt.Errorf("%s is %d MB; zips over 50 MB must be uploaded to S3 and deployed with --s3-bucket and --s3-key", name, info.Size()>>20)
+	}
+	return path, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
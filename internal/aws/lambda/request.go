@@ -0,0 +1,121 @@
+package lambda
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/askparse"
+)
+
+// Operation is what a question asks of Lambda
+type Operation int
+
+const (
+	// List shows functions with their runtime, memory and error rate
+	List Operation = iota + 1
+	// Errors shows a function's recent invocation errors
+	Errors
+	// SetEnv plans an environment variable change
+	SetEnv
+	// Publish plans a code deploy and publishes a new version
+	Publish
+)
+
+// Request is a question translated into a Lambda operation
+type Request struct {
+	Op       Operation
+	Function string
+	// Set and Unset are the environment variable changes for SetEnv
+	Set   map[string]string
+	Unset []string
+	// ZipFile or ImageURI is the code Publish deploys; with neither, the
+	// current code is published as a new version
+	ZipFile  string
+	ImageURI string
+}
+
+var (
+	lambdaRe    = regexp.MustCompile(`(?i)\blambdas?\b`)
+	notLambdaRe = regexp.MustCompile(`(?i)\b(?:cost|costs|spend|spending|bill|billing|price|pricing|layers?|create|new function)\b`)
+	// listRe asks about what the listing adds to an inventory; plain counts
+	// and inventories stay with the general AWS agent
+	listRe       = regexp.MustCompile(`(?i)\b(?:error rates?|error counts?|runtimes?|memory|invocations?|throttl(?:es|ed|ing)|failing|failures?|unhealthy|health)\b`)
+	errorsRe     = regexp.MustCompile(`(?i)\b(?:errors?|exceptions?|failures?|failing|failed|timeouts?|timed out|crash(?:es|ing)?)\b`)
+	errorRateRe  = regexp.MustCompile(`(?i)\berror[- ]rates?\b`)
+	envRe        = regexp.MustCompile(`(?i)\benv(?:ironment)?\b|\bvariables?\b|\bvars?\b`)
+	envPairRe    = regexp.MustCompile(`\b([A-Za-z][A-Za-z0-9_]*)\s*=\s*("[^"]*"|'[^']*'|[^\s,;]+)`)
+	unsetRe      = regexp.MustCompile(`\b(?i:unset|remove|delete|drop)\s+(?i:the\s+)?(?i:env(?:ironment)?\s+)?(?i:var(?:iable)?s?\s+)?([A-Z][A-Z0-9_]*(?:(?:\s*,\s*|\s+and\s+)[A-Z][A-Z0-9_]*)*)\b`)
+	unsetNameRe  = regexp.MustCompile(`[A-Z][A-Z0-9_]*`)
+	publishRe    = regexp.MustCompile(`(?i)\b(?:publish|deploy|update|upload|push|ship|roll out)\b`)
+	newVersionRe = regexp.MustCompile(`(?i)\b(?:new version|publish)\b`)
+	zipRe        = regexp.MustCompile(`["']?((?:[~.]{0,2}/)?[\w./-]*\w\.zip)\b["']?`)
+	imageRe      = regexp.MustCompile(`\b\d{12}\.dkr\.ecr\.[a-z0-9-]+\.amazonaws\.com(?:\.cn)?/[\w./-]+(?::[\w.-]+|@sha256:[0-9a-f]{64})?`)
+
+	// functionNames skips words the name patterns can land on that never
+	// name a function
+	functionNames = askparse.Names{
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)\b(?:lambda|function)\s+(?:function\s+)?(?:named\s+|called\s+)?["'` + "`" + `]?([\w-]+)`),
+			regexp.MustCompile(`(?i)\b([\w-]+)\s+(?:lambda|function)\b`),
+			regexp.MustCompile(`(?i)\b(?:publish|deploy)\s+(?:a\s+new\s+version\s+of\s+)?([\w-]+)`),
+		},
+		Skip: askparse.NotNames("lambda", "lambdas", "function", "functions", "aws", "its", "it",
+			"new", "version", "each", "every", "recent", "latest", "env", "environment", "variables", "vars",
+			"errors", "show", "list", "set", "update", "publish", "deploy", "error", "failing", "failed",
+			"failures", "timing", "crashing", "was"),
+	}
+)
+
+// ParseRequest reads a Lambda operation from question. Questions that ask
+// for no change and no function's errors are listings.
+func ParseRequest(question string) Request {
+	req := Request{Op: List}
+	rest := question
+	if m := imageRe.FindString(question); m != "" {
+		req.ImageURI = m
+		rest = strings.Replace(rest, m, " ", 1)
+	} else if m := zipRe.FindStringSubmatch(question); m != nil {
+		req.ZipFile = m[1]
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+
+	set := map[string]string{}
+	var unset []string
+	if envRe.MatchString(question) {
+		for _, m := range envPairRe.FindAllStringSubmatch(rest, -1) {
+			set[m[1]] = strings.Trim(m[2], `"'`)
+			rest = strings.Replace(rest, m[0], " ", 1)
+		}
+		if m := unsetRe.FindStringSubmatch(rest); m != nil {
+			unset = unsetNameRe.FindAllString(m[1], -1)
+			rest = strings.Replace(rest, m[0], " ", 1)
+		}
+	}
+	req.Function = functionNames.Find(rest)
+
+	switch {
+	case len(set) > 0 || len(unset) > 0:
+		req.Op = SetEnv
+		req.Set, req.Unset = set, unset
+	case req.ZipFile != "" || req.ImageURI != "":
+		req.Op = Publish
+	case publishRe.MatchString(question) && newVersionRe.MatchString(question) && req.Function != "":
+		req.Op = Publish
+	case req.Function != "" && errorsRe.MatchString(question) && !errorRateRe.MatchString(question):
+		req.Op = Errors
+	}
+	return req
+}
+
+// IsLambdaQuestion reports whether question asks to list Lambda functions,
+// see a function's errors, change its environment or deploy its code,
+// rather than to create one or about spend
+func IsLambdaQuestion(question string) bool {
+	if !lambdaRe.MatchString(question) || notLambdaRe.MatchString(question) {
+		return false
+	}
+	if ParseRequest(question).Op != List {
+		return true
+	}
+	return listRe.MatchString(question)
+}
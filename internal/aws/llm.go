@@ -3,6 +3,7 @@ package aws
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"time"
@@ -2596,13 +2597,24 @@ func (c *Client) executeAWSOperation(ctx context.Context, toolName string, input
 }
 
 // execAWSCLI executes AWS CLI commands directly
+// cliCommand builds an aws CLI command for args with the client's profile
+// (or assumed-role credentials) and region
+func (c *Client) cliCommand(ctx context.Context, args []string, profile *AIProfile) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "aws")
+	cmd.Args = append(cmd.Args, args...)
+	if len(c.cliEnv) > 0 {
+		cmd.Env = append(cliEnvWithoutProfile(os.Environ()), c.cliEnv...)
+		cmd.Args = append(cmd.Args, "--region", profile.Region, "--no-cli-pager")
+	} else {
+		cmd.Args = append(cmd.Args, "--profile", profile.AWSProfile, "--region", profile.Region, "--no-cli-pager")
+	}
+	return cmd
+}
+
 func (c *Client) execAWSCLI(ctx context.Context, args []string, profile *AIProfile) (string, error) {
 	verbose := viper.GetBool("debug")
 
-	// Build AWS CLI command
-	cmd := exec.CommandContext(ctx, "aws")
-	cmd.Args = append(cmd.Args, args...)
-	cmd.Args = append(cmd.Args, "--profile", profile.AWSProfile, "--region", profile.Region, "--no-cli-pager")
+	cmd := c.cliCommand(ctx, args, profile)
 
 	if c.debug || verbose {
 		fmt.Printf("🚀 Executing: %s\n", strings.Join(cmd.Args, " "))
@@ -0,0 +1,220 @@
+// Package logs answers questions about AWS logs ("show errors in lambda
+// checkout from the last hour") with CloudWatch Logs Insights. The question
+// is turned into log groups, a time window and an Insights query; the query
+// runs through the aws CLI and is printed with its results so it can be
+// refined and run again.
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/aws"
+)
+
+// maxLogGroups bounds how many log groups a name pattern expands to.
+// Logs Insights accepts at most 50 per query.
+const maxLogGroups = 10
+
+// pollAttempts and pollDelay bound the wait for a query to finish
+var (
+	pollAttempts = 60
+	pollDelay    = time.Second
+)
+
+// Agent runs Logs Insights queries
+type Agent struct {
+	run   aws.Runner
+	debug bool
+}
+
+// NewAgent creates a logs agent that calls CloudWatch Logs through run
+func NewAgent(run aws.Runner, debug bool) *Agent {
+	return &Agent{run: run, debug: debug}
+}
+
+// Field is one column of a result row
+type Field struct {
+	Name  string `json:"field"`
+	Value string `json:"value"`
+}
+
+// Result is a finished query and the rows it returned
+type Result struct {
+	Query          Query
+	Rows           [][]Field
+	RecordsMatched float64
+	RecordsScanned float64
+	BytesScanned   float64
+}
+
+// HandleQuery answers question and returns the formatted report
+func (a *Agent) HandleQuery(ctx context.Context, question string, now time.Time) (string, error) {
+	result, err := a.Run(ctx, ParseQuery(question, now))
+	if err != nil {
+		return "", err
+	}
+	return Format(result, question), nil
+}
+
+// Run resolves the query's log groups, starts it and waits for the results
+func (a *Agent) Run(ctx context.Context, q Query) (*Result, error) {
+	groups, err := a.resolveLogGroups(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	q.LogGroups = groups
+	if a.debug {
+		fmt.Printf("[aws-logs] %s on %s:\n%s\n", q.Label, strings.Join(groups, ", "), q.QueryString)
+	}
+
+	out, err := a.run(ctx, q.Args())
+	if err != nil {
+		return nil, fmt.Errorf("failed to start Logs Insights query: %w", err)
+	}
+	var started struct {
+		QueryID string `json:"queryId"`
+	}
+	if err := json.Unmarshal([]byte(out), &started); err != nil || started.QueryID == "" {
+		return nil, fmt.Errorf("failed to parse start-query output: %s", strings.TrimSpace(out))
+	}
+
+	for attempt := 0; attempt < pollAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				a.stop(started.QueryID)
+				return nil, ctx.Err()
+			case <-time.After(pollDelay):
+			}
+		}
+		out, err := a.run(ctx, []string{"logs", "get-query-results", "--query-id", started.QueryID, "--output", "json"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get query results: %w", err)
+		}
+		var resp queryResultsOutput
+		if err := json.Unmarshal([]byte(out), &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse query results: %w", err)
+		}
+		switch resp.Status {
+		case "Complete":
+			return resp.result(q), nil
+		case "Failed", "Cancelled", "Timeout":
+			return nil, fmt.Errorf("Logs Insights query %s: %s", strings.ToLower(resp.Status), q.QueryString)
+		}
+	}
+	a.stop(started.QueryID)
+	return nil, fmt.Errorf("Logs Insights query did not finish after %d checks", pollAttempts)
+}
+
+// stop cancels a query that is no longer waited for
+func (a *Agent) stop(queryID string) {
+	if _, err := a.run(context.Background(), []string{"logs", "stop-query", "--query-id", queryID}); err != nil && a.debug {
+		fmt.Printf("[aws-logs] failed to stop query %s: %v\n", queryID, err)
+	}
+}
+
+// resolveLogGroups returns the query's log groups, looking up those named
+// only by pattern
+func (a *Agent) resolveLogGroups(ctx context.Context, q Query) ([]string, error) {
+	groups := append([]string(nil), q.LogGroups...)
+	for _, t := range q.Targets {
+		if t.Pattern == "" {
+			continue
+		}
+		out, err := a.run(ctx, []string{"logs", "describe-log-groups", "--log-group-name-pattern", t.Pattern, "--max-items", strconv.Itoa(maxLogGroups), "--output", "json"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up log groups matching %q: %w", t.Pattern, err)
+		}
+		var resp struct {
+			LogGroups []struct {
+				LogGroupName string `json:"logGroupName"`
+			} `json:"logGroups"`
+		}
+		if err := json.Unmarshal([]byte(out), &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse log groups: %w", err)
+		}
+		if len(resp.LogGroups) == 0 {
+			return nil, fmt.Errorf("no log groups match %q", t.Pattern)
+		}
+		for _, g := range resp.LogGroups {
+			groups = append(groups, g.LogGroupName)
+		}
+	}
+
+	seen := map[string]bool{}
+	unique := groups[:0]
+	for _, g := range groups {
+		if !seen[g] {
+			seen[g] = true
+			unique = append(unique, g)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("name a log group or Lambda function to query, e.g. \"errors in lambda checkout from the last hour\"")
+	}
+	return unique, nil
+}
+
+// Args returns the aws logs start-query arguments for q
+func (q Query) Args() []string {
+	args := []string{"logs", "start-query", "--log-group-names"}
+	args = append(args, q.LogGroups...)
+	return append(args,
+		"--start-time", strconv.FormatInt(q.Start.Unix(), 10),
+		"--end-time", strconv.FormatInt(q.End.Unix(), 10),
+		"--query-string", q.QueryString,
+		"--output", "json",
+	)
+}
+
+// queryResultsOutput is the part of aws logs get-query-results output used
+// here
+type queryResultsOutput struct {
+	Status     string    `json:"status"`
+	Results    [][]Field `json:"results"`
+	Statistics struct {
+		RecordsMatched float64 `json:"recordsMatched"`
+		RecordsScanned float64 `json:"recordsScanned"`
+		BytesScanned   float64 `json:"bytesScanned"`
+	} `json:"statistics"`
+}
+
+func (r queryResultsOutput) result(q Query) *Result {
+	result := &Result{
+		Query:          q,
+		RecordsMatched: r.Statistics.RecordsMatched,
+		RecordsScanned: r.Statistics.RecordsScanned,
+		BytesScanned:   r.Statistics.BytesScanned,
+	}
+	for _, row := range r.Results {
+		fields := make([]Field, 0, len(row))
+		for _, f := range row {
+			if f.Name != "@ptr" {
+				fields = append(fields, f)
+			}
+		}
+		result.Rows = append(result.Rows, fields)
+	}
+	if q.Stats {
+		// Stats buckets come back in no particular order
+		sort.SliceStable(result.Rows, func(i, j int) bool {
+			return binValue(result.Rows[i]) < binValue(result.Rows[j])
+		})
+	}
+	return result
+}
+
+func binValue(row []Field) string {
+	for _, f := range row {
+		if strings.HasPrefix(f.Name, "bin(") {
+			return f.Value
+		}
+	}
+	return ""
+}
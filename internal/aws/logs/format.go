@@ -0,0 +1,155 @@
+package logs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// maxMessageWidth truncates long log messages in the listing
+const maxMessageWidth = 200
+
+// barWidth is the width of the longest bar in a stats chart
+const barWidth = 30
+
+// Format renders a result as the matching events, or a count per time
+// bucket for stats queries, followed by the query so it can be refined
+func Format(r *Result, question string) string {
+	q := r.Query
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "CloudWatch Logs, %s (%s to %s)\n", q.Label,
+		q.Start.UTC().Format(time.DateTime), q.End.UTC().Format(time.DateTime))
+	fmt.Fprintf(&sb, "Log groups: %s\n", strings.Join(q.LogGroups, ", "))
+
+	switch {
+	case len(r.Rows) == 0:
+		sb.WriteString("\nNo matching log events.\n")
+	case q.Stats:
+		formatStats(&sb, r.Rows)
+	default:
+		formatEvents(&sb, r.Rows)
+	}
+	fmt.Fprintf(&sb, "\nMatched %s of %s records scanned (%s).\n",
+		count(r.RecordsMatched), count(r.RecordsScanned), size(r.BytesScanned))
+
+	sb.WriteString("\nLogs Insights query:\n")
+	for _, line := range strings.Split(q.QueryString, "\n") {
+		fmt.Fprintf(&sb, "  %s\n", line)
+	}
+	sb.WriteString("\nRefine it and run it again with:\n  clanker agents aws-logs")
+	for _, g := range q.LogGroups {
+		fmt.Fprintf(&sb, " --log-group %s", shellQuote(g))
+	}
+	fmt.Fprintf(&sb, " --query %s %s\n", shellQuote(q.QueryString), shellQuote(question))
+	return sb.String()
+}
+
+func formatEvents(sb *strings.Builder, rows [][]Field) {
+	fmt.Fprintf(sb, "\n%d events, newest first:\n", len(rows))
+	for _, row := range rows {
+		var ts, stream string
+		var rest []string
+		for _, f := range row {
+			switch f.Name {
+			case "@timestamp":
+				ts = f.Value
+			case "@logStream":
+				stream = f.Value
+			case "@message":
+				rest = append(rest, oneLine(f.Value))
+			default:
+				rest = append(rest, f.Name+"="+oneLine(f.Value))
+			}
+		}
+		fmt.Fprintf(sb, "  %s  %s\n", ts, strings.Join(rest, "  "))
+		if stream != "" {
+			fmt.Fprintf(sb, "    stream: %s\n", stream)
+		}
+	}
+}
+
+// formatStats renders stats rows as a table, charting the last numeric
+// column
+func formatStats(sb *strings.Builder, rows [][]Field) {
+	var peak float64
+	values := make([]float64, len(rows))
+	for i, row := range rows {
+		for _, f := range row {
+			if v, err := strconv.ParseFloat(f.Value, 64); err == nil && !strings.HasPrefix(f.Name, "bin(") {
+				values[i] = v
+			}
+		}
+		peak = math.Max(peak, values[i])
+	}
+	sb.WriteString("\n")
+	w := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+	var header []string
+	for _, f := range rows[0] {
+		header = append(header, strings.ToUpper(f.Name))
+	}
+	fmt.Fprintf(w, "  %s\t\n", strings.Join(header, "\t"))
+	for i, row := range rows {
+		cells := make([]string, 0, len(row))
+		for _, f := range row {
+			cells = append(cells, f.Value)
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", strings.Join(cells, "\t"), bar(values[i], peak))
+	}
+	w.Flush()
+}
+
+// oneLine folds a multi-line message onto one line and truncates it
+func oneLine(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > maxMessageWidth {
+		return string(r[:maxMessageWidth-1]) + "…"
+	}
+	return s
+}
+
+func bar(value, peak float64) string {
+	if peak <= 0 || value <= 0 {
+		return ""
+	}
+	return strings.Repeat("█", max(1, int(math.Round(value/peak*barWidth))))
+}
+
+// count formats a whole number with thousands separators
+func count(n float64) string {
+	s := fmt.Sprintf("%.0f", n)
+	var out []byte
+	for i := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			out = append(out, ',')
+		}
+		out = append(out, s[i])
+	}
+	return string(out)
+}
+
+// size formats a byte count in the largest unit below 1024
+func size(bytes float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	i := 0
+	for bytes >= 1024 && i < len(units)-1 {
+		bytes /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", bytes, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", bytes, units[i])
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:@", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,227 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, time.October, 15, 9, 30, 0, 0, time.UTC)
+
+func TestParseTargets(t *testing.T) {
+	tests := []struct {
+		question string
+		want     []Target
+	}{
+		{"show errors in lambda checkout from the last hour", []Target{{Name: "Lambda checkout", LogGroup: "/aws/lambda/checkout"}}},
+		{"any timeouts in the payments-api lambda today", []Target{{Name: "Lambda payments-api", LogGroup: "/aws/lambda/payments-api"}}},
+		{"errors in /ecs/orders over the past 30 minutes", []Target{{Name: "/ecs/orders", LogGroup: "/ecs/orders"}}},
+		{"warnings in log group orders", []Target{{Name: "orders", Pattern: "orders"}}},
+		{"errors in service billing", []Target{{Name: "billing", Pattern: "billing"}}},
+		{"which lambda functions have errors", nil},
+		{"show errors in the logs", nil},
+	}
+	for _, tt := range tests {
+		if got := ParseTargets(tt.question); !slices.Equal(got, tt.want) {
+			t.Errorf("ParseTargets(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		question string
+		since    time.Duration
+		label    string
+	}{
+		{"errors in lambda checkout", time.Hour, "last hour"},
+		{"errors in lambda checkout from the last hour", time.Hour, "last hour"},
+		{"errors in lambda checkout in the past 30 minutes", 30 * time.Minute, "last 30 minutes"},
+		{"errors in lambda checkout over the last three days", 72 * time.Hour, "last 3 days"},
+		{"errors in lambda checkout in the last 2h", 2 * time.Hour, "last 2 hours"},
+		{"errors in lambda checkout since 15 minutes ago", 15 * time.Minute, "since 15 minutes ago"},
+		{"errors in lambda checkout today", 9*time.Hour + 30*time.Minute, "today"},
+	}
+	for _, tt := range tests {
+		q := ParseQuery(tt.question, testNow)
+		if !q.End.Equal(testNow) || q.End.Sub(q.Start) != tt.since || q.Label != tt.label {
+			t.Errorf("%q: got %s %q, want %s %q", tt.question, q.End.Sub(q.Start), q.Label, tt.since, tt.label)
+		}
+	}
+
+	q := ParseQuery("errors in lambda checkout yesterday", testNow)
+	if q.Start != time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC) || q.End != time.Date(2026, time.October, 15, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("yesterday = %s..%s", q.Start, q.End)
+	}
+}
+
+func TestQueryString(t *testing.T) {
+	tests := []struct {
+		question string
+		want     string
+	}{
+		{
+			"show errors in lambda checkout from the last hour",
+			"fields @timestamp, @logStream, @message\n| filter @message like /(?i)(error|exception|fail|panic)/\n| sort @timestamp desc\n| limit 50",
+		},
+		{
+			`last 20 log lines containing "order/42" in lambda checkout`,
+			"fields @timestamp, @logStream, @message\n| filter @message like /order\\/42/\n| sort @timestamp desc\n| limit 20",
+		},
+		{
+			"how many timeouts per minute in lambda checkout over the last 6 hours",
+			"fields @timestamp, @logStream, @message\n| filter @message like /(?i)(timed out|timeout)/\n| stats count(*) as events by bin(5m)",
+		},
+		{
+			"cold starts in lambda checkout today",
+			"fields @timestamp, @logStream, @initDuration, @duration\n| filter @type = \"REPORT\" and ispresent(@initDuration)\n| sort @timestamp desc\n| limit 50",
+		},
+	}
+	for _, tt := range tests {
+		if got := ParseQuery(tt.question, testNow).QueryString; got != tt.want {
+			t.Errorf("%q:\ngot:\n%s\nwant:\n%s", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestSetQueryString(t *testing.T) {
+	q := ParseQuery("errors in lambda checkout", testNow)
+	q.SetQueryString("filter @message like /oops/ | stats count(*) by bin(1h)")
+	if !q.Stats {
+		t.Error("a refined stats query should be formatted as stats")
+	}
+	q.SetQueryString("fields @message | limit 5")
+	if q.Stats {
+		t.Error("a refined listing query should not be formatted as stats")
+	}
+}
+
+// fakeLogs answers aws logs calls, reporting the query as running once
+type fakeLogs struct {
+	calls [][]string
+	polls int
+}
+
+func (f *fakeLogs) run(ctx context.Context, args []string) (string, error) {
+	f.calls = append(f.calls, args)
+	switch args[1] {
+	case "describe-log-groups":
+		return `{"logGroups": [{"logGroupName": "/ecs/billing"}, {"logGroupName": "/ecs/billing-worker"}]}`, nil
+	case "start-query":
+		return `{"queryId": "q-1"}`, nil
+	case "get-query-results":
+		f.polls++
+		if f.polls == 1 {
+			return `{"status": "Running"}`, nil
+		}
+		return `{"status": "Complete", "statistics": {"recordsMatched": 2, "recordsScanned": 12345, "bytesScanned": 2048000},
+			"results": [
+				[{"field": "@timestamp", "value": "2026-10-15 09:20:01.000"}, {"field": "@logStream", "value": "2026/10/15/[$LATEST]abc"}, {"field": "@message", "value": "ERROR payment\n  declined"}, {"field": "@ptr", "value": "x"}],
+				[{"field": "@timestamp", "value": "2026-10-15 09:10:00.000"}, {"field": "@message", "value": "ERROR timeout"}]
+			]}`, nil
+	}
+	return "", errors.New("unexpected call: " + strings.Join(args, " "))
+}
+
+func TestRunPollsAndFormats(t *testing.T) {
+	prevDelay := pollDelay
+	pollDelay = 0
+	t.Cleanup(func() { pollDelay = prevDelay })
+
+	aws := &fakeLogs{}
+	question := "show errors in lambda checkout from the last hour"
+	out, err := NewAgent(aws.run, false).HandleQuery(context.Background(), question, testNow)
+	if err != nil {
+		t.Fatalf("HandleQuery: %v", err)
+	}
+	if aws.polls != 2 {
+		t.Errorf("polled %d times, want 2", aws.polls)
+	}
+	start := aws.calls[0]
+	if !slices.Equal(start[:4], []string{"logs", "start-query", "--log-group-names", "/aws/lambda/checkout"}) {
+		t.Errorf("start-query args = %v", start)
+	}
+	if i := slices.Index(start, "--start-time"); start[i+1] != strconv.FormatInt(testNow.Add(-time.Hour).Unix(), 10) {
+		t.Errorf("start time = %s", start[i+1])
+	}
+	for _, want := range []string{
+		"CloudWatch Logs, last hour (2026-10-15 08:30:00 to 2026-10-15 09:30:00)",
+		"2 events, newest first:",
+		"2026-10-15 09:20:01.000  ERROR payment declined",
+		"stream: 2026/10/15/[$LATEST]abc",
+		"Matched 2 of 12,345 records scanned (2.0 MB).",
+		"| filter @message like /(?i)(error|exception|fail|panic)/",
+		"clanker agents aws-logs --log-group /aws/lambda/checkout --query 'fields @timestamp",
+		"'show errors in lambda checkout from the last hour'",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "@ptr") {
+		t.Error("@ptr should be dropped from results")
+	}
+}
+
+func TestRunResolvesPatterns(t *testing.T) {
+	pollDelay = 0
+	t.Cleanup(func() { pollDelay = time.Second })
+
+	aws := &fakeLogs{}
+	result, err := NewAgent(aws.run, false).Run(context.Background(), ParseQuery("errors in service billing", testNow))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !slices.Equal(result.Query.LogGroups, []string{"/ecs/billing", "/ecs/billing-worker"}) {
+		t.Errorf("log groups = %v", result.Query.LogGroups)
+	}
+
+	if _, err := NewAgent(aws.run, false).Run(context.Background(), ParseQuery("show errors in the logs", testNow)); err == nil {
+		t.Error("expected an error when no log group is named")
+	}
+}
+
+func TestRunFailedQuery(t *testing.T) {
+	run := func(ctx context.Context, args []string) (string, error) {
+		if args[1] == "start-query" {
+			return `{"queryId": "q-1"}`, nil
+		}
+		return `{"status": "Failed"}`, nil
+	}
+	_, err := NewAgent(run, false).Run(context.Background(), ParseQuery("errors in lambda checkout", testNow))
+	if err == nil || !strings.Contains(err.Error(), "query failed") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestIsLogsQuestion(t *testing.T) {
+	tests := map[string]bool{
+		"show errors in lambda checkout from the last hour": true,
+		"logs for /ecs/orders in the past 30 minutes":       true,
+		"how many timeouts in the payments lambda today":    true,
+		"what does lambda checkout do":                      false,
+		"show me recent error logs for prod api":            false,
+		"set retention on log group orders to 30 days":      false,
+	}
+	for q, want := range tests {
+		if got := IsLogsQuestion(q); got != want {
+			t.Errorf("IsLogsQuestion(%q) = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"/aws/lambda/checkout": "/aws/lambda/checkout",
+		"it's here":            `'it'\''s here'`,
+		"":                     "''",
+	} {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package logs
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default and maximum number of log events a query returns. Logs Insights
+// caps limit at 10000.
+const (
+	defaultLimit = 50
+	maxLimit     = 10000
+)
+
+// Target is a log source named in the question. LogGroup is set when the
+// question names the group outright (or a Lambda function, whose group name
+// is fixed); otherwise Pattern is matched against log group names.
+type Target struct {
+	Name     string
+	LogGroup string
+	Pattern  string
+}
+
+// Query is a question translated into a Logs Insights query
+type Query struct {
+	Targets []Target
+	// LogGroups are the resolved log group names; ParseQuery fills in the
+	// ones known without a lookup
+	LogGroups []string
+	Start     time.Time
+	End       time.Time
+	Label     string
+	// QueryString is the Logs Insights query that runs; users can refine it
+	// and pass it back with --query
+	QueryString string
+	// Stats is true when the query counts events over time instead of
+	// listing them
+	Stats bool
+}
+
+// filter is a message filter a question can ask for
+type filter struct {
+	re   *regexp.Regexp
+	expr string
+}
+
+var filters = []filter{
+	{regexp.MustCompile(`\b(?:errors?|exceptions?|fail(?:ed|ures?|ing)?|panics?|5xx)\b`), `@message like /(?i)(error|exception|fail|panic)/`},
+	{regexp.MustCompile(`\b(?:timeouts?|timed out)\b`), `@message like /(?i)(timed out|timeout)/`},
+	{regexp.MustCompile(`\bwarn(?:ings?)?\b`), `@message like /(?i)warn/`},
+}
+
+var (
+	coldStartRe    = regexp.MustCompile(`\bcold ?starts?\b`)
+	statsRe        = regexp.MustCompile(`\b(?:how many|count|number of|per (?:minute|hour|day)|over time|trend|rate)\b`)
+	statsCommandRe = regexp.MustCompile(`(?:^|\|)\s*stats\s`)
+	quotedRe       = regexp.MustCompile("\"([^\"]+)\"|'([^']+)'|`([^`]+)`")
+	limitRe        = regexp.MustCompile(`\b(\d+)\s+(?:most recent\s+|latest\s+|recent\s+)?(?:errors?|lines?|log lines|events?|entries|messages?|logs?|exceptions?|cold ?starts?|timeouts?|warnings?)\b`)
+
+	logGroupPathRe = regexp.MustCompile(`(?:^|\s)(/[\w\-./#]+[\w\-#])`)
+	logGroupRe     = regexp.MustCompile(`(?i)\blog[- ]group\s+(?:named\s+|called\s+)?["']?([\w\-./#]+)`)
+	lambdaRe       = regexp.MustCompile(`(?i)\b(?:lambda(?:\s+function)?|function)\s+(?:named\s+|called\s+)?["']?([\w\-]+)`)
+	lambdaAfterRe  = regexp.MustCompile(`(?i)\b([\w\-]+)\s+lambda\b`)
+	serviceRe      = regexp.MustCompile(`(?i)\b(?:ecs\s+service|service|app)\s+(?:named\s+|called\s+)?["']?([\w\-]+)`)
+)
+
+// stopwords are words the target patterns can capture that are never names
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "any": true, "are": true, "at": true, "aws": true, "by": true,
+	"called": true, "during": true, "error": true, "errors": true, "for": true, "from": true,
+	"function": true, "functions": true, "has": true, "have": true, "in": true, "is": true,
+	"last": true, "log": true, "logs": true, "my": true, "named": true, "of": true, "on": true,
+	"or": true, "our": true, "over": true, "past": true, "per": true, "since": true, "that": true,
+	"the": true, "this": true, "to": true, "today": true, "was": true, "were": true, "with": true,
+	"yesterday": true, "which": true, "what": true, "show": true, "each": true, "every": true,
+}
+
+// ParseQuery reads the log source, time range and filters from question and
+// builds the Logs Insights query
+func ParseQuery(question string, now time.Time) Query {
+	q := Query{Targets: ParseTargets(question)}
+	for _, t := range q.Targets {
+		if t.LogGroup != "" {
+			q.LogGroups = append(q.LogGroups, t.LogGroup)
+		}
+	}
+	q.Start, q.End, q.Label = parseWindow(question, now)
+
+	lower := strings.ToLower(question)
+	q.Stats = statsRe.MatchString(lower)
+	q.QueryString = buildQueryString(question, q.Stats, q.End.Sub(q.Start))
+	return q
+}
+
+// SetSince replaces the query window with the given duration up to now
+func (q *Query) SetSince(since time.Duration, now time.Time) {
+	q.Start, q.End = now.Add(-since), now
+	q.Label = "last " + durationLabel(since)
+}
+
+// SetLogGroups replaces the log groups named in the question
+func (q *Query) SetLogGroups(groups []string) {
+	q.Targets = nil
+	q.LogGroups = append([]string(nil), groups...)
+}
+
+// SetQueryString replaces the generated query, as when a user refines it
+func (q *Query) SetQueryString(query string) {
+	q.QueryString = strings.TrimSpace(query)
+	q.Stats = statsCommandRe.MatchString(q.QueryString)
+}
+
+// ParseTargets returns the log sources a question names, in order
+func ParseTargets(question string) []Target {
+	var targets []Target
+	seen := map[string]bool{}
+	add := func(t Target) {
+		key := t.LogGroup + "\x00" + t.Pattern
+		if !seen[key] {
+			seen[key] = true
+			targets = append(targets, t)
+		}
+	}
+
+	for _, m := range logGroupPathRe.FindAllStringSubmatch(question, -1) {
+		add(Target{Name: m[1], LogGroup: m[1]})
+	}
+	for _, m := range logGroupRe.FindAllStringSubmatch(question, -1) {
+		if name := m[1]; !stopwords[strings.ToLower(name)] && !strings.HasPrefix(name, "/") {
+			add(Target{Name: name, Pattern: name})
+		}
+	}
+	for _, re := range []*regexp.Regexp{lambdaRe, lambdaAfterRe} {
+		for _, m := range re.FindAllStringSubmatch(question, -1) {
+			if name := m[1]; !stopwords[strings.ToLower(name)] && !strings.EqualFold(name, "lambda") {
+				add(Target{Name: "Lambda " + name, LogGroup: "/aws/lambda/" + name})
+			}
+		}
+	}
+	for _, m := range serviceRe.FindAllStringSubmatch(question, -1) {
+		if name := m[1]; !stopwords[strings.ToLower(name)] {
+			add(Target{Name: name, Pattern: name})
+		}
+	}
+	return targets
+}
+
+func buildQueryString(question string, stats bool, window time.Duration) string {
+	lower := strings.ToLower(question)
+	var lines []string
+	coldStarts := coldStartRe.MatchString(lower)
+	if coldStarts {
+		lines = append(lines, "fields @timestamp, @logStream, @initDuration, @duration",
+			`filter @type = "REPORT" and ispresent(@initDuration)`)
+	} else {
+		lines = append(lines, "fields @timestamp, @logStream, @message")
+		for _, f := range filters {
+			if f.re.MatchString(lower) {
+				lines = append(lines, "filter "+f.expr)
+				break
+			}
+		}
+	}
+	for _, m := range quotedRe.FindAllStringSubmatch(question, -1) {
+		text := m[1] + m[2] + m[3]
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		lines = append(lines, "filter @message like /"+escapeRegex(text)+"/")
+	}
+
+	if stats {
+		lines = append(lines, fmt.Sprintf("stats count(*) as events by bin(%s)", binFor(window)))
+	} else {
+		lines = append(lines, "sort @timestamp desc", fmt.Sprintf("limit %d", parseLimit(lower)))
+	}
+	return strings.Join(lines, "\n| ")
+}
+
+// escapeRegex quotes text for a /.../ pattern in a Logs Insights query
+func escapeRegex(text string) string {
+	return strings.ReplaceAll(regexp.QuoteMeta(text), "/", `\/`)
+}
+
+func parseLimit(lower string) int {
+	m := limitRe.FindStringSubmatch(lower)
+	if m == nil {
+		return defaultLimit
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n <= 0 {
+		return defaultLimit
+	}
+	return min(n, maxLimit)
+}
+
+// binFor picks a stats bin that splits window into at most about 70 buckets
+func binFor(window time.Duration) string {
+	switch {
+	case window <= time.Hour:
+		return "1m"
+	case window <= 6*time.Hour:
+		return "5m"
+	case window <= 24*time.Hour:
+		return "30m"
+	case window <= 7*24*time.Hour:
+		return "3h"
+	}
+	return "1d"
+}
+
+var (
+	windowCountRe = regexp.MustCompile(`\b(?:last|past|previous)\s+(\d+|an?|one|two|three|four|five|six|seven|eight|nine|ten|twelve|fifteen|twenty|thirty)\s*(m|mins?|minutes?|h|hrs?|hours?|d|days?|w|weeks?)\b`)
+	windowUnitRe  = regexp.MustCompile(`\b(?:last|past|previous)\s+(minute|hour|day|week)\b`)
+	agoRe         = regexp.MustCompile(`\b(\d+|an?|one|two|three|four|five|six|seven|eight|nine|ten|twelve|fifteen|twenty|thirty)\s*(m|mins?|minutes?|h|hrs?|hours?|d|days?|w|weeks?)\s+ago\b`)
+	todayRe       = regexp.MustCompile(`\btoday\b`)
+	yesterdayRe   = regexp.MustCompile(`\byesterday\b`)
+)
+
+var numberWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7,
+	"eight": 8, "nine": 9, "ten": 10, "twelve": 12, "fifteen": 15, "twenty": 20, "thirty": 30,
+}
+
+// parseWindow reads the time range from question. Without one it covers the
+// last hour.
+func parseWindow(question string, now time.Time) (time.Time, time.Time, string) {
+	lower := strings.ToLower(question)
+	if m := windowCountRe.FindStringSubmatch(lower); m != nil {
+		if d, label, ok := duration(m[1], m[2]); ok {
+			return now.Add(-d), now, "last " + label
+		}
+	}
+	if m := agoRe.FindStringSubmatch(lower); m != nil {
+		if d, label, ok := duration(m[1], m[2]); ok {
+			return now.Add(-d), now, "since " + label + " ago"
+		}
+	}
+	if m := windowUnitRe.FindStringSubmatch(lower); m != nil {
+		d, label, _ := duration("1", m[1])
+		return now.Add(-d), now, "last " + label
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if yesterdayRe.MatchString(lower) {
+		return midnight.AddDate(0, 0, -1), midnight, "yesterday"
+	}
+	if todayRe.MatchString(lower) {
+		return midnight, now, "today"
+	}
+	return now.Add(-time.Hour), now, "last hour"
+}
+
+// duration converts a count and unit from the question into a duration and
+// a label like "3 hours" or "hour"
+func duration(count, unit string) (time.Duration, string, bool) {
+	n, ok := numberWords[count]
+	if !ok {
+		var err error
+		if n, err = strconv.Atoi(count); err != nil || n <= 0 {
+			return 0, "", false
+		}
+	}
+	var d time.Duration
+	switch unit[0] {
+	case 'm':
+		d = time.Minute
+	case 'h':
+		d = time.Hour
+	case 'd':
+		d = 24 * time.Hour
+	default:
+		d = 7 * 24 * time.Hour
+	}
+	d *= time.Duration(n)
+	return d, durationLabel(d), true
+}
+
+// durationLabel describes d in its largest whole unit, such as "hour",
+// "3 hours" or "2 weeks"
+func durationLabel(d time.Duration) string {
+	n, name := int(d/time.Minute), "minute"
+	for _, u := range []struct {
+		d    time.Duration
+		name string
+	}{{7 * 24 * time.Hour, "week"}, {24 * time.Hour, "day"}, {time.Hour, "hour"}} {
+		if d >= u.d && d%u.d == 0 {
+			n, name = int(d/u.d), u.name
+			break
+		}
+	}
+	if n == 1 {
+		return name
+	}
+	return fmt.Sprintf("%d %ss", n, name)
+}
+
+var (
+	logIntentRe    = regexp.MustCompile(`\b(?:logs?|log groups?|log lines|errors?|exceptions?|timeouts?|timed out|warnings?|cold ?starts?|stack ?traces?|panics?)\b`)
+	changeIntentRe = regexp.MustCompile(`\b(?:create|delete|remove|deploy|update|configure|set up|setup|enable|disable|retention|subscribe|subscription|export)\b`)
+)
+
+// IsLogsQuestion reports whether question asks to read logs from a source
+// it names, such as "show errors in lambda checkout from the last hour"
+func IsLogsQuestion(question string) bool {
+	lower := strings.ToLower(question)
+	if !logIntentRe.MatchString(lower) || changeIntentRe.MatchString(lower) {
+		return false
+	}
+	return len(ParseTargets(question)) > 0
+}
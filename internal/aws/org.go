@@ -0,0 +1,233 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/batch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultOrgRoleName is the role assumed in member accounts when
+// aws.organization.role_name is not set. Organizations creates it in every
+// account it vends.
+const DefaultOrgRoleName = "OrganizationAccountAccessRole"
+
+// DefaultOrgConcurrency is how many accounts are queried at once when
+// aws.organization.concurrency is not set
+const DefaultOrgConcurrency = 4
+
+// OrgAccount is an active account in the caller's organization
+type OrgAccount struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+}
+
+// OrgRoleName returns aws.organization.role_name, or DefaultOrgRoleName
+func OrgRoleName() string {
+	if name := strings.TrimSpace(viper.GetString("aws.organization.role_name")); name != "" {
+		return name
+	}
+	return DefaultOrgRoleName
+}
+
+// OrgConcurrency returns aws.organization.concurrency, or DefaultOrgConcurrency
+func OrgConcurrency() int {
+	if n := viper.GetInt("aws.organization.concurrency"); n > 0 {
+		return n
+	}
+	return DefaultOrgConcurrency
+}
+
+// AccountID returns the account the client's credentials belong to
+func (c *Client) AccountID(ctx context.Context) (string, error) {
+	out, err := sts.NewFromConfig(c.cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	return aws.ToString(out.Account), nil
+}
+
+// ListOrgAccounts returns the organization's active accounts, sorted by name.
+// It needs organizations:ListAccounts, so the client must belong to the
+// management account or a delegated administrator.
+func (c *Client) ListOrgAccounts(ctx context.Context) ([]OrgAccount, error) {
+	out, err := c.execCLI(ctx, []string{"organizations", "list-accounts", "--output", "json"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization accounts: %w", err)
+	}
+	return parseOrgAccounts(out)
+}
+
+func parseOrgAccounts(out string) ([]OrgAccount, error) {
+	var resp struct {
+		Accounts []struct {
+			OrgAccount
+			Status string `json:"Status"`
+		} `json:"Accounts"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse organization accounts: %w", err)
+	}
+	var accounts []OrgAccount
+	for _, a := range resp.Accounts {
+		if a.Status == "" || a.Status == "ACTIVE" {
+			accounts = append(accounts, a.OrgAccount)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		if accounts[i].Name != accounts[j].Name {
+			return accounts[i].Name < accounts[j].Name
+		}
+		return accounts[i].ID < accounts[j].ID
+	})
+	return accounts, nil
+}
+
+// AssumeAccount returns a client for accountID using roleName in that
+// account. SDK and CLI calls both use the assumed session.
+func (c *Client) AssumeAccount(ctx context.Context, accountID, roleName string) (*Client, error) {
+	roleARN := fmt.Sprintf("arn:%s:iam::%s:role/%s", partitionForRegion(c.cfg.Region), accountID, roleName)
+	out, err := sts.NewFromConfig(c.cfg).AssumeRole(ctx, &sts.AssumeRoleInput{
+		RoleArn:         aws.String(roleARN),
+		RoleSessionName: aws.String("clanker-" + accountID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume %s: %w", roleARN, err)
+	}
+	if out.Credentials == nil {
+		return nil, fmt.Errorf("assuming %s returned no credentials", roleARN)
+	}
+
+	keyID, secret, token := aws.ToString(out.Credentials.AccessKeyId), aws.ToString(out.Credentials.SecretAccessKey), aws.ToString(out.Credentials.SessionToken)
+	cfg := c.cfg.Copy()
+	cfg.Credentials = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(keyID, secret, token))
+	return &Client{
+		cfg:            cfg,
+		profile:        "account-" + accountID,
+		debug:          c.debug,
+		ec2:            ec2.NewFromConfig(cfg),
+		ecs:            ecs.NewFromConfig(cfg),
+		iam:            iam.NewFromConfig(cfg),
+		lambda:         lambda.NewFromConfig(cfg),
+		rds:            rds.NewFromConfig(cfg),
+		s3:             s3.NewFromConfig(cfg),
+		batch:          batch.NewFromConfig(cfg),
+		cloudwatch:     cloudwatch.NewFromConfig(cfg),
+		cloudwatchlogs: cloudwatchlogs.NewFromConfig(cfg),
+		cliEnv: []string{
+			"AWS_ACCESS_KEY_ID=" + keyID,
+			"AWS_SECRET_ACCESS_KEY=" + secret,
+			"AWS_SESSION_TOKEN=" + token,
+		},
+	}, nil
+}
+
+// GetOrgContext gathers GetRelevantContext from every active account in the
+// organization, at most OrgConcurrency at a time, and labels each section
+// with its account. The caller's own account uses the client directly;
+// others are reached by assuming OrgRoleName. An account that cannot be
+// reached is noted in its section instead of failing the whole lookup.
+func (c *Client) GetOrgContext(ctx context.Context, question string) (string, error) {
+	accounts, err := c.ListOrgAccounts(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(accounts) == 0 {
+		return "", fmt.Errorf("no active accounts found in the organization")
+	}
+	self, err := c.AccountID(ctx)
+	if err != nil {
+		return "", err
+	}
+	roleName := OrgRoleName()
+
+	sections := make([]string, len(accounts))
+	failed := make([]bool, len(accounts))
+	var g errgroup.Group
+	g.SetLimit(OrgConcurrency())
+	for i, account := range accounts {
+		g.Go(func() error {
+			content, err := c.accountContext(ctx, account, self, roleName, question)
+			if err != nil {
+				failed[i] = true
+				content = fmt.Sprintf("Unavailable: %v\n", err)
+			}
+			sections[i] = fmt.Sprintf("=== Account: %s (%s) ===\n%s", account.Name, account.ID, content)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	reached := 0
+	for _, f := range failed {
+		if !f {
+			reached++
+		}
+	}
+	if reached == 0 {
+		return "", fmt.Errorf("no organization account could be queried (role %s): %s", roleName, strings.TrimSpace(sections[0]))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "AWS Organization: %d of %d accounts queried (role %s)\n\n", reached, len(accounts), roleName)
+	for _, section := range sections {
+		sb.WriteString(section)
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+func (c *Client) accountContext(ctx context.Context, account OrgAccount, self, roleName, question string) (string, error) {
+	client := c
+	if account.ID != self {
+		assumed, err := c.AssumeAccount(ctx, account.ID, roleName)
+		if err != nil {
+			return "", err
+		}
+		client = assumed
+	}
+	if c.debug {
+		fmt.Printf("[aws-org] gathering context for %s (%s)\n", account.Name, account.ID)
+	}
+	return client.GetRelevantContext(ctx, question)
+}
+
+// partitionForRegion returns the ARN partition region belongs to
+func partitionForRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	}
+	return "aws"
+}
+
+// cliEnvWithoutProfile drops profile selection from env so CLI calls use the
+// session credentials set alongside it
+func cliEnvWithoutProfile(env []string) []string {
+	out := make([]string, 0, len(env))
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "AWS_PROFILE=") || strings.HasPrefix(kv, "AWS_DEFAULT_PROFILE=") {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseOrgAccounts(t *testing.T) {
+	out := `{"Accounts": [
+		{"Id": "222222222222", "Name": "prod", "Status": "ACTIVE"},
+		{"Id": "333333333333", "Name": "old", "Status": "SUSPENDED"},
+		{"Id": "111111111111", "Name": "dev", "Status": "ACTIVE"}
+	]}`
+	accounts, err := parseOrgAccounts(out)
+	if err != nil {
+		t.Fatalf("parseOrgAccounts: %v", err)
+	}
+	want := []OrgAccount{{ID: "111111111111", Name: "dev"}, {ID: "222222222222", Name: "prod"}}
+	if !slices.Equal(accounts, want) {
+		t.Errorf("accounts = %+v, want %+v", accounts, want)
+	}
+
+	if _, err := parseOrgAccounts("not json"); err == nil {
+		t.Error("expected an error for invalid output")
+	}
+}
+
+func TestOrgSettings(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Set("aws.organization.role_name", nil)
+		viper.Set("aws.organization.concurrency", nil)
+	})
+
+	if OrgRoleName() != DefaultOrgRoleName || OrgConcurrency() != DefaultOrgConcurrency {
+		t.Errorf("defaults = %q, %d", OrgRoleName(), OrgConcurrency())
+	}
+	viper.Set("aws.organization.role_name", "ClankerReadOnly")
+	viper.Set("aws.organization.concurrency", 8)
+	if OrgRoleName() != "ClankerReadOnly" || OrgConcurrency() != 8 {
+		t.Errorf("overrides = %q, %d", OrgRoleName(), OrgConcurrency())
+	}
+}
+
+func TestPartitionForRegion(t *testing.T) {
+	for region, want := range map[string]string{
+		"us-east-1":     "aws",
+		"us-gov-west-1": "aws-us-gov",
+		"cn-north-1":    "aws-cn",
+		"":              "aws",
+	} {
+		if got := partitionForRegion(region); got != want {
+			t.Errorf("partitionForRegion(%q) = %q, want %q", region, got, want)
+		}
+	}
+}
+
+func TestCLIEnvWithoutProfile(t *testing.T) {
+	env := []string{"HOME=/root", "AWS_PROFILE=prod", "AWS_DEFAULT_PROFILE=prod", "AWS_REGION=us-east-1"}
+	want := []string{"HOME=/root", "AWS_REGION=us-east-1"}
+	if got := cliEnvWithoutProfile(env); !slices.Equal(got, want) {
+		t.Errorf("cliEnvWithoutProfile = %v, want %v", got, want)
+	}
+}
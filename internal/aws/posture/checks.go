@@ -0,0 +1,391 @@
+package posture
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// bucketConcurrency bounds the per-bucket S3 lookups
+const bucketConcurrency = 8
+
+// credentialReportAttempts and credentialReportDelay bound the wait for IAM
+// to generate the credential report
+var (
+	credentialReportAttempts = 10
+	credentialReportDelay    = 2 * time.Second
+)
+
+// riskyPorts are the ports that must never be open to the internet
+var riskyPorts = []struct {
+	port int
+	name string
+}{
+	{22, "SSH"},
+	{3389, "RDP"},
+}
+
+const publicAccessBlockAll = "BlockPublicAcls=true,IgnorePublicAcls=true,BlockPublicPolicy=true,RestrictPublicBuckets=true"
+
+func (a *Agent) runJSON(ctx context.Context, out any, args ...string) error {
+	raw, err := a.run(ctx, append(args, "--output", "json"))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("failed to parse aws %s %s output: %w", args[0], args[1], err)
+	}
+	return nil
+}
+
+// isMissing reports whether err is AWS saying the configuration does not
+// exist, which for these lookups means "not set"
+func isMissing(err error, code string) bool {
+	return err != nil && strings.Contains(err.Error(), code)
+}
+
+type publicAccessBlock struct {
+	BlockPublicAcls       bool
+	IgnorePublicAcls      bool
+	BlockPublicPolicy     bool
+	RestrictPublicBuckets bool
+}
+
+func (b publicAccessBlock) all() bool {
+	return b.BlockPublicAcls && b.IgnorePublicAcls && b.BlockPublicPolicy && b.RestrictPublicBuckets
+}
+
+func checkS3(ctx context.Context, a *Agent) ([]Finding, error) {
+	var buckets struct {
+		Buckets []struct{ Name string }
+	}
+	if err := a.runJSON(ctx, &buckets, "s3api", "list-buckets"); err != nil {
+		return nil, err
+	}
+	if len(buckets.Buckets) == 0 {
+		return nil, nil
+	}
+	// With Block Public Access on for the whole account, no bucket can be
+	// made public, so the per-bucket settings do not matter
+	if a.accountPublicAccessBlocked(ctx) {
+		return nil, nil
+	}
+
+	found := make([][]Finding, len(buckets.Buckets))
+	var g errgroup.Group
+	g.SetLimit(bucketConcurrency)
+	for i, b := range buckets.Buckets {
+		g.Go(func() error {
+			found[i] = a.checkBucket(ctx, b.Name)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	var findings []Finding
+	for _, f := range found {
+		findings = append(findings, f...)
+	}
+	return findings, nil
+}
+
+func (a *Agent) accountPublicAccessBlocked(ctx context.Context) bool {
+	var identity struct{ Account string }
+	if err := a.runJSON(ctx, &identity, "sts", "get-caller-identity"); err != nil || identity.Account == "" {
+		return false
+	}
+	var resp struct {
+		PublicAccessBlockConfiguration publicAccessBlock
+	}
+	if err := a.runJSON(ctx, &resp, "s3control", "get-public-access-block", "--account-id", identity.Account); err != nil {
+		return false
+	}
+	return resp.PublicAccessBlockConfiguration.all()
+}
+
+func (a *Agent) checkBucket(ctx context.Context, bucket string) []Finding {
+	fix := []string{"aws", "s3api", "put-public-access-block", "--bucket", bucket, "--public-access-block-configuration", publicAccessBlockAll}
+	remediation := "Enable all four S3 Block Public Access settings on the bucket"
+
+	var block struct {
+		PublicAccessBlockConfiguration publicAccessBlock
+	}
+	err := a.runJSON(ctx, &block, "s3api", "get-public-access-block", "--bucket", bucket)
+	if err != nil && !isMissing(err, "NoSuchPublicAccessBlockConfiguration") {
+		if a.debug {
+			fmt.Printf("[aws-audit] skipping bucket %s: %v\n", bucket, err)
+		}
+		return nil
+	}
+	if block.PublicAccessBlockConfiguration.all() {
+		return nil
+	}
+
+	var status struct {
+		PolicyStatus struct{ IsPublic bool }
+	}
+	if err := a.runJSON(ctx, &status, "s3api", "get-bucket-policy-status", "--bucket", bucket); err == nil && status.PolicyStatus.IsPublic {
+		return []Finding{{Severity: Critical, Check: "s3", Resource: bucket, Title: "bucket policy grants public access", Remediation: remediation, Fix: fix}}
+	}
+
+	var acl struct {
+		Grants []struct {
+			Grantee struct{ URI string }
+		}
+	}
+	if err := a.runJSON(ctx, &acl, "s3api", "get-bucket-acl", "--bucket", bucket); err == nil {
+		for _, grant := range acl.Grants {
+			if strings.HasSuffix(grant.Grantee.URI, "/AllUsers") || strings.HasSuffix(grant.Grantee.URI, "/AuthenticatedUsers") {
+				return []Finding{{Severity: Critical, Check: "s3", Resource: bucket, Title: "bucket ACL grants public access", Remediation: remediation, Fix: fix}}
+			}
+		}
+	}
+
+	return []Finding{{Severity: Medium, Check: "s3", Resource: bucket, Title: "Block Public Access is not fully enabled", Remediation: remediation, Fix: fix}}
+}
+
+type ipPermission struct {
+	IpProtocol string
+	FromPort   *int
+	ToPort     *int
+	IpRanges   []struct{ CidrIp string }
+	Ipv6Ranges []struct{ CidrIpv6 string }
+}
+
+func checkSecurityGroups(ctx context.Context, a *Agent) ([]Finding, error) {
+	var resp struct {
+		SecurityGroups []struct {
+			GroupId       string
+			GroupName     string
+			IpPermissions []ipPermission
+		}
+	}
+	if err := a.runJSON(ctx, &resp, "ec2", "describe-security-groups"); err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, sg := range resp.SecurityGroups {
+		resource := fmt.Sprintf("%s (%s)", sg.GroupId, sg.GroupName)
+		for _, perm := range sg.IpPermissions {
+			var open []string
+			for _, r := range perm.IpRanges {
+				if r.CidrIp == "0.0.0.0/0" {
+					open = append(open, r.CidrIp)
+				}
+			}
+			for _, r := range perm.Ipv6Ranges {
+				if r.CidrIpv6 == "::/0" {
+					open = append(open, r.CidrIpv6)
+				}
+			}
+			for _, cidr := range open {
+				if perm.IpProtocol == "-1" {
+					findings = append(findings, Finding{
+						Severity: Critical, Check: "security-group", Resource: resource,
+						Title:       fmt.Sprintf("all traffic open to %s", cidr),
+						Remediation: "Revoke the rule and allow only the ports and source ranges that need access",
+						Fix:         revokeIngress(sg.GroupId, perm, cidr),
+					})
+					continue
+				}
+				for _, p := range riskyPorts {
+					if !perm.covers(p.port) {
+						continue
+					}
+					findings = append(findings, Finding{
+						Severity: High, Check: "security-group", Resource: resource,
+						Title:       fmt.Sprintf("%s (port %d) open to %s", p.name, p.port, cidr),
+						Remediation: "Revoke the rule; use SSM Session Manager or restrict the source to known addresses",
+						Fix:         revokeIngress(sg.GroupId, perm, cidr),
+					})
+				}
+			}
+		}
+	}
+	return findings, nil
+}
+
+func (p ipPermission) covers(port int) bool {
+	if p.IpProtocol != "tcp" && p.IpProtocol != "6" {
+		return false
+	}
+	if p.FromPort == nil || p.ToPort == nil {
+		return true
+	}
+	return *p.FromPort <= port && port <= *p.ToPort
+}
+
+// revokeIngress returns the command that removes cidr from one rule
+func revokeIngress(groupID string, perm ipPermission, cidr string) []string {
+	rule := map[string]any{"IpProtocol": perm.IpProtocol}
+	if perm.FromPort != nil && perm.ToPort != nil {
+		rule["FromPort"] = *perm.FromPort
+		rule["ToPort"] = *perm.ToPort
+	}
+	if strings.Contains(cidr, ":") {
+		rule["Ipv6Ranges"] = []map[string]string{{"CidrIpv6": cidr}}
+	} else {
+		rule["IpRanges"] = []map[string]string{{"CidrIp": cidr}}
+	}
+	permissions, _ := json.Marshal([]any{rule})
+	return []string{"aws", "ec2", "revoke-security-group-ingress", "--group-id", groupID, "--ip-permissions", string(permissions)}
+}
+
+func checkEBS(ctx context.Context, a *Agent) ([]Finding, error) {
+	var findings []Finding
+	var byDefault struct{ EbsEncryptionByDefault bool }
+	if err := a.runJSON(ctx, &byDefault, "ec2", "get-ebs-encryption-by-default"); err != nil {
+		return nil, err
+	}
+	if !byDefault.EbsEncryptionByDefault {
+		findings = append(findings, Finding{
+			Severity: Medium, Check: "ebs", Resource: "account default",
+			Title:       "new EBS volumes are not encrypted by default",
+			Remediation: "Turn on EBS encryption by default for the region",
+			Fix:         []string{"aws", "ec2", "enable-ebs-encryption-by-default"},
+		})
+	}
+
+	var volumes struct {
+		Volumes []struct {
+			VolumeId    string
+			Attachments []struct{ InstanceId string }
+		}
+	}
+	if err := a.runJSON(ctx, &volumes, "ec2", "describe-volumes", "--filters", "Name=encrypted,Values=false"); err != nil {
+		return nil, err
+	}
+	for _, v := range volumes.Volumes {
+		resource := v.VolumeId
+		if len(v.Attachments) > 0 && v.Attachments[0].InstanceId != "" {
+			resource += " on " + v.Attachments[0].InstanceId
+		}
+		findings = append(findings, Finding{
+			Severity: Medium, Check: "ebs", Resource: resource,
+			Title:       "volume is not encrypted",
+			Remediation: "Snapshot the volume, copy the snapshot with --encrypted, and swap in a volume created from the copy",
+		})
+	}
+	return findings, nil
+}
+
+func checkRDS(ctx context.Context, a *Agent) ([]Finding, error) {
+	var resp struct {
+		DBInstances []struct {
+			DBInstanceIdentifier string
+			Engine               string
+			StorageEncrypted     bool
+		}
+	}
+	if err := a.runJSON(ctx, &resp, "rds", "describe-db-instances"); err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, db := range resp.DBInstances {
+		if db.StorageEncrypted {
+			continue
+		}
+		findings = append(findings, Finding{
+			Severity: High, Check: "rds", Resource: fmt.Sprintf("%s (%s)", db.DBInstanceIdentifier, db.Engine),
+			Title:       "storage is not encrypted",
+			Remediation: "Snapshot the instance, copy the snapshot with --kms-key-id, and restore from the encrypted copy",
+		})
+	}
+	return findings, nil
+}
+
+func checkRoot(ctx context.Context, a *Agent) ([]Finding, error) {
+	var resp struct {
+		SummaryMap map[string]int
+	}
+	if err := a.runJSON(ctx, &resp, "iam", "get-account-summary"); err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	if resp.SummaryMap["AccountAccessKeysPresent"] > 0 {
+		findings = append(findings, Finding{
+			Severity: Critical, Check: "root", Resource: "root account",
+			Title:       "root user has access keys",
+			Remediation: "Sign in as root and delete the access keys under Security credentials; use IAM roles instead",
+		})
+	}
+	if resp.SummaryMap["AccountMFAEnabled"] == 0 {
+		findings = append(findings, Finding{
+			Severity: Critical, Check: "root", Resource: "root account",
+			Title:       "root user has no MFA device",
+			Remediation: "Sign in as root and assign an MFA device under Security credentials",
+		})
+	}
+	return findings, nil
+}
+
+func checkUserMFA(ctx context.Context, a *Agent) ([]Finding, error) {
+	report, err := a.credentialReport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := csv.NewReader(strings.NewReader(report)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credential report: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	col := map[string]int{}
+	for i, name := range rows[0] {
+		col[name] = i
+	}
+	userCol, passwordCol, mfaCol := col["user"], col["password_enabled"], col["mfa_active"]
+	var findings []Finding
+	for _, row := range rows[1:] {
+		if len(row) <= max(userCol, passwordCol, mfaCol) || row[userCol] == "<root_account>" {
+			continue
+		}
+		if row[passwordCol] == "true" && row[mfaCol] == "false" {
+			findings = append(findings, Finding{
+				Severity: High, Check: "iam-mfa", Resource: row[userCol],
+				Title:       "console user has no MFA device",
+				Remediation: "Assign an MFA device to the user, or remove the console password if it is not needed",
+			})
+		}
+	}
+	return findings, nil
+}
+
+// credentialReport generates the IAM credential report and returns it as CSV
+func (a *Agent) credentialReport(ctx context.Context) (string, error) {
+	for attempt := 0; ; attempt++ {
+		var state struct{ State string }
+		if err := a.runJSON(ctx, &state, "iam", "generate-credential-report"); err != nil {
+			return "", err
+		}
+		if state.State == "COMPLETE" {
+			break
+		}
+		if attempt+1 >= credentialReportAttempts {
+			return "", fmt.Errorf("credential report was not ready after %d attempts", credentialReportAttempts)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(credentialReportDelay):
+		}
+	}
+
+	var resp struct{ Content string }
+	if err := a.runJSON(ctx, &resp, "iam", "get-credential-report"); err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.Content)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode credential report: %w", err)
+	}
+	return string(data), nil
+}
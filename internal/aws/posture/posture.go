@@ -0,0 +1,210 @@
+// Package posture audits an AWS account's security basics: public S3
+// buckets, security groups open to the internet on SSH or RDP, unencrypted
+// EBS volumes and RDS instances, root access keys and users without MFA.
+// Findings are ranked by severity, and the ones the AWS CLI can fix are
+// turned into a maker plan for clanker plan apply.
+package posture
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/aws"
+	"github.com/bgdnvk/clanker/internal/maker"
+	"golang.org/x/sync/errgroup"
+)
+
+// Severity ranks a finding; lower values are more severe
+type Severity int
+
+const (
+	Critical Severity = iota
+	High
+	Medium
+	Low
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Critical:
+		return "CRITICAL"
+	case High:
+		return "HIGH"
+	case Medium:
+		return "MEDIUM"
+	}
+	return "LOW"
+}
+
+// Finding is one security issue found by a check
+type Finding struct {
+	Severity    Severity `json:"severity"`
+	Check       string   `json:"check"`
+	Resource    string   `json:"resource"`
+	Title       string   `json:"title"`
+	Remediation string   `json:"remediation"`
+	// Fix is the aws CLI command that remediates the finding; it is empty
+	// when the fix needs a console sign-in or a data migration
+	Fix []string `json:"fix,omitempty"`
+}
+
+// Agent runs the posture checks
+type Agent struct {
+	run   aws.Runner
+	debug bool
+}
+
+// NewAgent creates a posture agent that calls AWS through run
+func NewAgent(run aws.Runner, debug bool) *Agent {
+	return &Agent{run: run, debug: debug}
+}
+
+// Report is the outcome of an audit
+type Report struct {
+	Findings []Finding
+	// Skipped lists checks that could not run, with the reason
+	Skipped []string
+}
+
+// check is one posture check
+type check struct {
+	name string
+	run  func(ctx context.Context, a *Agent) ([]Finding, error)
+}
+
+var checks = []check{
+	{name: "S3 public access", run: checkS3},
+	{name: "Security groups", run: checkSecurityGroups},
+	{name: "EBS encryption", run: checkEBS},
+	{name: "RDS encryption", run: checkRDS},
+	{name: "Root account", run: checkRoot},
+	{name: "IAM user MFA", run: checkUserMFA},
+}
+
+// Audit runs every check concurrently. A check that fails is listed in
+// Skipped; the others still report.
+func (a *Agent) Audit(ctx context.Context) *Report {
+	results := make([][]Finding, len(checks))
+	errs := make([]error, len(checks))
+	var g errgroup.Group
+	for i, c := range checks {
+		g.Go(func() error {
+			start := time.Now()
+			results[i], errs[i] = c.run(ctx, a)
+			if a.debug {
+				fmt.Printf("[aws-audit] %s: %d findings in %s\n", c.name, len(results[i]), time.Since(start).Round(time.Millisecond))
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	report := &Report{}
+	for i, c := range checks {
+		if errs[i] != nil {
+			report.Skipped = append(report.Skipped, fmt.Sprintf("%s: %v", c.name, errs[i]))
+			continue
+		}
+		report.Findings = append(report.Findings, results[i]...)
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		fi, fj := report.Findings[i], report.Findings[j]
+		if fi.Severity != fj.Severity {
+			return fi.Severity < fj.Severity
+		}
+		if fi.Check != fj.Check {
+			return fi.Check < fj.Check
+		}
+		return fi.Resource < fj.Resource
+	})
+	return report
+}
+
+// Format renders the report with findings grouped by severity
+func (r *Report) Format() string {
+	var sb strings.Builder
+	counts := map[Severity]int{}
+	for _, f := range r.Findings {
+		counts[f.Severity]++
+	}
+	fmt.Fprintf(&sb, "AWS Security Posture: %d findings (%d critical, %d high, %d medium, %d low)\n",
+		len(r.Findings), counts[Critical], counts[High], counts[Medium], counts[Low])
+
+	current := Severity(-1)
+	for _, f := range r.Findings {
+		if f.Severity != current {
+			current = f.Severity
+			fmt.Fprintf(&sb, "\n%s\n", current)
+		}
+		fmt.Fprintf(&sb, "  - [%s] %s: %s\n", f.Check, f.Resource, f.Title)
+		fmt.Fprintf(&sb, "    Fix: %s\n", f.Remediation)
+	}
+	if len(r.Findings) == 0 {
+		sb.WriteString("\nNo issues found by the checks that ran.\n")
+	}
+	if len(r.Skipped) > 0 {
+		sb.WriteString("\nChecks that could not run:\n")
+		for _, s := range r.Skipped {
+			fmt.Fprintf(&sb, "  - %s\n", s)
+		}
+	}
+	return sb.String()
+}
+
+// RemediationPlan returns a maker plan with a command for every finding
+// that has a Fix, most severe first, or nil when none do
+func (r *Report) RemediationPlan(question string) *maker.Plan {
+	plan := &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: time.Now().UTC(),
+		Provider:  "aws",
+		Question:  question,
+	}
+	manual := 0
+	seen := map[string]bool{}
+	for _, f := range r.Findings {
+		if len(f.Fix) == 0 {
+			manual++
+			continue
+		}
+		// One rule open on both SSH and RDP is revoked once
+		key := strings.Join(f.Fix, "\x00")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		plan.Commands = append(plan.Commands, maker.Command{
+			Args:   append([]string(nil), f.Fix...),
+			Reason: fmt.Sprintf("%s %s: %s", f.Severity, f.Resource, f.Title),
+		})
+	}
+	if len(plan.Commands) == 0 {
+		return nil
+	}
+	plan.Summary = fmt.Sprintf("Remediate %d AWS security findings", len(plan.Commands))
+	if manual > 0 {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("%d findings need manual remediation; see the audit report", manual))
+	}
+	return plan
+}
+
+// IsAuditQuestion reports whether question asks for a security posture
+// review of the account rather than about one IAM principal
+func IsAuditQuestion(question string) bool {
+	q := strings.ToLower(question)
+	if strings.Contains(q, "how secure") {
+		return true
+	}
+	if !strings.Contains(q, "security") && !strings.Contains(q, "posture") {
+		return false
+	}
+	for _, phrase := range []string{"audit", "posture", "security review", "security check", "security scan", "security assessment"} {
+		if strings.Contains(q, phrase) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package posture
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeAWS answers aws CLI calls by the longest matching argument prefix
+type fakeAWS map[string]string
+
+func (f fakeAWS) run(ctx context.Context, args []string) (string, error) {
+	cmd := strings.Join(args, " ")
+	best := ""
+	for prefix := range f {
+		if strings.HasPrefix(cmd, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return "", errors.New("unexpected call: aws " + cmd)
+	}
+	out := f[best]
+	if strings.HasPrefix(out, "error:") {
+		return "", errors.New(strings.TrimPrefix(out, "error:"))
+	}
+	return out, nil
+}
+
+func credentialReportJSON(csv string) string {
+	return `{"Content": "` + base64.StdEncoding.EncodeToString([]byte(csv)) + `"}`
+}
+
+func insecureAccount() fakeAWS {
+	return fakeAWS{
+		"s3api list-buckets":                `{"Buckets": [{"Name": "public-site"}, {"Name": "acl-open"}, {"Name": "loose"}, {"Name": "locked"}]}`,
+		"sts get-caller-identity":           `{"Account": "123456789012"}`,
+		"s3control get-public-access-block": "error:An error occurred (NoSuchPublicAccessBlockConfiguration)",
+
+		"s3api get-public-access-block --bucket public-site":  "error:An error occurred (NoSuchPublicAccessBlockConfiguration)",
+		"s3api get-bucket-policy-status --bucket public-site": `{"PolicyStatus": {"IsPublic": true}}`,
+
+		"s3api get-public-access-block --bucket acl-open":  `{"PublicAccessBlockConfiguration": {"BlockPublicAcls": false}}`,
+		"s3api get-bucket-policy-status --bucket acl-open": "error:An error occurred (NoSuchBucketPolicy)",
+		"s3api get-bucket-acl --bucket acl-open":           `{"Grants": [{"Grantee": {"URI": "http://acs.amazonaws.com/groups/global/AllUsers"}}]}`,
+
+		"s3api get-public-access-block --bucket loose":  "error:An error occurred (NoSuchPublicAccessBlockConfiguration)",
+		"s3api get-bucket-policy-status --bucket loose": "error:An error occurred (NoSuchBucketPolicy)",
+		"s3api get-bucket-acl --bucket loose":           `{"Grants": []}`,
+
+		"s3api get-public-access-block --bucket locked": `{"PublicAccessBlockConfiguration": {"BlockPublicAcls": true, "IgnorePublicAcls": true, "BlockPublicPolicy": true, "RestrictPublicBuckets": true}}`,
+
+		"ec2 describe-security-groups": `{"SecurityGroups": [
+			{"GroupId": "sg-1", "GroupName": "bastion", "IpPermissions": [
+				{"IpProtocol": "tcp", "FromPort": 22, "ToPort": 22, "IpRanges": [{"CidrIp": "0.0.0.0/0"}]},
+				{"IpProtocol": "tcp", "FromPort": 443, "ToPort": 443, "IpRanges": [{"CidrIp": "0.0.0.0/0"}]}
+			]},
+			{"GroupId": "sg-2", "GroupName": "wide", "IpPermissions": [
+				{"IpProtocol": "tcp", "FromPort": 0, "ToPort": 65535, "Ipv6Ranges": [{"CidrIpv6": "::/0"}]}
+			]},
+			{"GroupId": "sg-3", "GroupName": "internal", "IpPermissions": [
+				{"IpProtocol": "tcp", "FromPort": 22, "ToPort": 22, "IpRanges": [{"CidrIp": "10.0.0.0/8"}]}
+			]}
+		]}`,
+
+		"ec2 get-ebs-encryption-by-default": `{"EbsEncryptionByDefault": false}`,
+		"ec2 describe-volumes":              `{"Volumes": [{"VolumeId": "vol-1", "Attachments": [{"InstanceId": "i-1"}]}]}`,
+		"rds describe-db-instances":         `{"DBInstances": [{"DBInstanceIdentifier": "orders", "Engine": "postgres", "StorageEncrypted": false}, {"DBInstanceIdentifier": "users", "Engine": "mysql", "StorageEncrypted": true}]}`,
+		"iam get-account-summary":           `{"SummaryMap": {"AccountAccessKeysPresent": 1, "AccountMFAEnabled": 0}}`,
+		"iam generate-credential-report":    `{"State": "COMPLETE"}`,
+		"iam get-credential-report": credentialReportJSON("user,arn,password_enabled,mfa_active\n" +
+			"<root_account>,arn:aws:iam::123456789012:root,not_supported,false\n" +
+			"alice,arn:aws:iam::123456789012:user/alice,true,false\n" +
+			"bob,arn:aws:iam::123456789012:user/bob,true,true\n" +
+			"ci,arn:aws:iam::123456789012:user/ci,false,false\n"),
+	}
+}
+
+func TestAuditFindsAndRanksIssues(t *testing.T) {
+	report := NewAgent(insecureAccount().run, false).Audit(context.Background())
+	if len(report.Skipped) != 0 {
+		t.Fatalf("unexpected skipped checks: %v", report.Skipped)
+	}
+
+	got := map[string]Severity{}
+	for _, f := range report.Findings {
+		got[f.Resource+": "+f.Title] = f.Severity
+	}
+	want := map[string]Severity{
+		"public-site: bucket policy grants public access":               Critical,
+		"acl-open: bucket ACL grants public access":                     Critical,
+		"loose: Block Public Access is not fully enabled":               Medium,
+		"sg-1 (bastion): SSH (port 22) open to 0.0.0.0/0":               High,
+		"sg-2 (wide): SSH (port 22) open to ::/0":                       High,
+		"sg-2 (wide): RDP (port 3389) open to ::/0":                     High,
+		"account default: new EBS volumes are not encrypted by default": Medium,
+		"vol-1 on i-1: volume is not encrypted":                         Medium,
+		"orders (postgres): storage is not encrypted":                   High,
+		"root account: root user has access keys":                       Critical,
+		"root account: root user has no MFA device":                     Critical,
+		"alice: console user has no MFA device":                         High,
+	}
+	for k, sev := range want {
+		if got[k] != sev {
+			t.Errorf("finding %q: severity %v, present %v", k, got[k], hasKey(got, k))
+		}
+	}
+	if len(report.Findings) != len(want) {
+		t.Errorf("got %d findings, want %d: %v", len(report.Findings), len(want), got)
+	}
+	for i := 1; i < len(report.Findings); i++ {
+		if report.Findings[i].Severity < report.Findings[i-1].Severity {
+			t.Fatalf("findings not ordered by severity: %+v", report.Findings)
+		}
+	}
+
+	out := report.Format()
+	for _, want := range []string{"12 findings (4 critical, 5 high, 3 medium, 0 low)", "CRITICAL\n", "HIGH\n", "MEDIUM\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func hasKey(m map[string]Severity, k string) bool {
+	_, ok := m[k]
+	return ok
+}
+
+func TestRemediationPlan(t *testing.T) {
+	report := NewAgent(insecureAccount().run, false).Audit(context.Background())
+	plan := report.RemediationPlan("audit my AWS security")
+	if plan == nil {
+		t.Fatal("expected a remediation plan")
+	}
+	if plan.Provider != "aws" || plan.Question != "audit my AWS security" {
+		t.Errorf("plan metadata = %+v", plan)
+	}
+
+	var cmds []string
+	for _, c := range plan.Commands {
+		if c.Args[0] != "aws" {
+			t.Errorf("command does not start with aws: %v", c.Args)
+		}
+		cmds = append(cmds, strings.Join(c.Args, " "))
+	}
+	joined := strings.Join(cmds, "\n")
+	for _, want := range []string{
+		"aws s3api put-public-access-block --bucket public-site",
+		"aws s3api put-public-access-block --bucket loose",
+		`aws ec2 revoke-security-group-ingress --group-id sg-1 --ip-permissions [{"FromPort":22,"IpProtocol":"tcp","IpRanges":[{"CidrIp":"0.0.0.0/0"}],"ToPort":22}]`,
+		"aws ec2 enable-ebs-encryption-by-default",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("plan missing %q:\n%s", want, joined)
+		}
+	}
+	// sg-2's rule is open on both SSH and RDP but revoked once
+	if n := strings.Count(joined, "--group-id sg-2"); n != 1 {
+		t.Errorf("sg-2 revoked %d times, want 1", n)
+	}
+	// Critical fixes come first
+	if !strings.HasPrefix(cmds[0], "aws s3api put-public-access-block") {
+		t.Errorf("first command = %q, want a critical S3 fix", cmds[0])
+	}
+	if len(plan.Notes) != 1 || !strings.Contains(plan.Notes[0], "5 findings need manual remediation") {
+		t.Errorf("notes = %v", plan.Notes)
+	}
+}
+
+func TestAuditAccountWidePublicAccessBlock(t *testing.T) {
+	aws := insecureAccount()
+	aws["s3control get-public-access-block"] = `{"PublicAccessBlockConfiguration": {"BlockPublicAcls": true, "IgnorePublicAcls": true, "BlockPublicPolicy": true, "RestrictPublicBuckets": true}}`
+	report := NewAgent(aws.run, false).Audit(context.Background())
+	for _, f := range report.Findings {
+		if f.Check == "s3" {
+			t.Errorf("unexpected S3 finding with account-wide block: %+v", f)
+		}
+	}
+}
+
+func TestAuditSkipsFailedChecks(t *testing.T) {
+	aws := insecureAccount()
+	aws["rds describe-db-instances"] = "error:AccessDenied"
+	report := NewAgent(aws.run, false).Audit(context.Background())
+	if len(report.Skipped) != 1 || !strings.Contains(report.Skipped[0], "RDS encryption: AccessDenied") {
+		t.Errorf("skipped = %v", report.Skipped)
+	}
+	if len(report.Findings) == 0 {
+		t.Error("other checks should still report")
+	}
+	if !strings.Contains(report.Format(), "Checks that could not run:") {
+		t.Error("report should list the skipped check")
+	}
+}
+
+func TestCredentialReportWaitsForGeneration(t *testing.T) {
+	prevDelay := credentialReportDelay
+	credentialReportDelay = 0
+	t.Cleanup(func() { credentialReportDelay = prevDelay })
+
+	calls := 0
+	run := func(ctx context.Context, args []string) (string, error) {
+		switch strings.Join(args[:2], " ") {
+		case "iam generate-credential-report":
+			calls++
+			if calls < 3 {
+				return `{"State": "STARTED"}`, nil
+			}
+			return `{"State": "COMPLETE"}`, nil
+		case "iam get-credential-report":
+			return credentialReportJSON("user,password_enabled,mfa_active\n"), nil
+		}
+		return "", errors.New("unexpected call")
+	}
+	if _, err := NewAgent(run, false).credentialReport(context.Background()); err != nil {
+		t.Fatalf("credentialReport: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("generate called %d times, want 3", calls)
+	}
+}
+
+func TestIsAuditQuestion(t *testing.T) {
+	tests := map[string]bool{
+		"audit my AWS security":               true,
+		"what is our security posture":        true,
+		"run a security check on the account": true,
+		"how secure is our aws account":       true,
+		"audit the iam role trust policy":     false,
+		"list security groups":                false,
+		"what did we spend on security hub":   false,
+	}
+	for q, want := range tests {
+		if got := IsAuditQuestion(q); got != want {
+			t.Errorf("IsAuditQuestion(%q) = %v, want %v", q, got, want)
+		}
+	}
+}
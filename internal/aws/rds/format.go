@@ -0,0 +1,110 @@
+package rds
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// lowStoragePercent is the free storage share below which an instance is
+// called out
+const lowStoragePercent = 10
+
+// Format renders the instances and clusters as tables with their latest
+// metrics, followed by instances running low on storage
+func (inv *Inventory) Format() string {
+	var sb strings.Builder
+	if len(inv.Instances) == 0 && len(inv.Clusters) == 0 {
+		return "No RDS instances or clusters found.\n"
+	}
+
+	if len(inv.Clusters) > 0 {
+		fmt.Fprintf(&sb, "DB clusters (%d):\n", len(inv.Clusters))
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  CLUSTER\tENGINE\tSTATUS\tMEMBERS\tVOLUME USED\tENDPOINT")
+		for _, c := range inv.Clusters {
+			members := make([]string, len(c.Members))
+			for i, m := range c.Members {
+				members[i] = m.ID
+				if m.Writer {
+					members[i] += "*"
+				}
+			}
+			volume := "-"
+			if v, ok := c.Metrics[MetricVolumeUsed]; ok {
+				volume = gib(v)
+			}
+			fmt.Fprintf(tw, "  %s\t%s %s\t%s\t%s\t%s\t%s\n", c.ID, c.Engine, c.EngineVersion, c.Status,
+				orDash(strings.Join(members, ",")), volume, endpoint(c.Endpoint, c.Port))
+		}
+		tw.Flush()
+		sb.WriteString("  (* writer)\n\n")
+	}
+
+	var low []string
+	if len(inv.Instances) > 0 {
+		fmt.Fprintf(&sb, "DB instances (%d):\n", len(inv.Instances))
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  INSTANCE\tENGINE\tCLASS\tSTATUS\tSTORAGE\tFREE\tCONNECTIONS\tCPU\tENDPOINT")
+		for _, i := range inv.Instances {
+			storage, free := "-", "-"
+			if i.Aurora() {
+				storage = "cluster " + i.Cluster
+			} else if i.AllocatedGB > 0 {
+				storage = fmt.Sprintf("%d GiB", i.AllocatedGB)
+			}
+			if v, ok := i.Metrics[MetricFreeStorage]; ok {
+				free = gib(v)
+				if i.AllocatedGB > 0 {
+					pct := v / (float64(i.AllocatedGB) * (1 << 30)) * 100
+					free += fmt.Sprintf(" (%.0f%%)", pct)
+					if pct < lowStoragePercent {
+						low = append(low, fmt.Sprintf("%s has %.0f%% of %d GiB free", i.ID, pct, i.AllocatedGB))
+					}
+				}
+			}
+			connections, cpu := "-", "-"
+			if v, ok := i.Metrics[MetricConnections]; ok {
+				connections = fmt.Sprintf("%.0f", v)
+			}
+			if v, ok := i.Metrics[MetricCPU]; ok {
+				cpu = fmt.Sprintf("%.1f%%", v)
+			}
+			engine := i.Engine + " " + i.EngineVersion
+			if i.MultiAZ {
+				engine += " (multi-AZ)"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", i.ID, engine, i.Class, i.Status,
+				storage, free, connections, cpu, endpoint(i.Endpoint, i.Port))
+		}
+		tw.Flush()
+	}
+
+	if len(low) > 0 {
+		sb.WriteString("\nLow on storage:\n")
+		for _, l := range low {
+			fmt.Fprintf(&sb, "  - %s\n", l)
+		}
+	}
+	if inv.MetricsErr != nil {
+		fmt.Fprintf(&sb, "\nMetrics unavailable: %v\n", inv.MetricsErr)
+	} else {
+		sb.WriteString("\nMetrics are the latest 5-minute averages from CloudWatch.\n")
+	}
+	return sb.String()
+}
+
+func endpoint(host string, port int) string {
+	if host == "" {
+		return "-"
+	}
+	if port == 0 {
+		return host
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
+
+// gib renders a byte count in GiB
+func gib(bytes float64) string {
+	return fmt.Sprintf("%.1f GiB", bytes/(1<<30))
+}
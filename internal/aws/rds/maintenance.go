@@ -0,0 +1,154 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PendingAction is maintenance RDS has scheduled or offers for a resource
+type PendingAction struct {
+	// ResourceARN identifies the instance or cluster for
+	// apply-pending-maintenance-action
+	ResourceARN string
+	Resource    string
+	Action      string
+	Description string
+	// AutoApplyAfter is when the action is applied in the next maintenance
+	// window; ForcedApply is when it is applied regardless of the window.
+	// Either may be zero.
+	AutoApplyAfter time.Time
+	ForcedApply    time.Time
+	OptIn          string
+}
+
+// MaintenanceReport lists pending maintenance with the maintenance window
+// of every instance and cluster
+type MaintenanceReport struct {
+	Actions   []PendingAction
+	Inventory *Inventory
+}
+
+// Maintenance returns the pending maintenance actions in the region and the
+// maintenance windows they will run in
+func (a *Agent) Maintenance(ctx context.Context) (*MaintenanceReport, error) {
+	inv, err := a.Describe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		PendingMaintenanceActions []struct {
+			ResourceIdentifier              string
+			PendingMaintenanceActionDetails []struct {
+				Action               string
+				Description          string
+				AutoAppliedAfterDate time.Time
+				ForcedApplyDate      time.Time
+				OptInStatus          string
+			}
+		}
+	}
+	if err := a.runJSON(ctx, &resp, "rds", "describe-pending-maintenance-actions"); err != nil {
+		return nil, fmt.Errorf("failed to describe pending maintenance: %w", err)
+	}
+	report := &MaintenanceReport{Inventory: inv}
+	for _, r := range resp.PendingMaintenanceActions {
+		for _, d := range r.PendingMaintenanceActionDetails {
+			report.Actions = append(report.Actions, PendingAction{
+				ResourceARN:    r.ResourceIdentifier,
+				Resource:       resourceName(r.ResourceIdentifier),
+				Action:         d.Action,
+				Description:    d.Description,
+				AutoApplyAfter: d.AutoAppliedAfterDate,
+				ForcedApply:    d.ForcedApplyDate,
+				OptIn:          d.OptInStatus,
+			})
+		}
+	}
+	sort.SliceStable(report.Actions, func(i, j int) bool {
+		return report.Actions[i].Resource < report.Actions[j].Resource
+	})
+	return report, nil
+}
+
+// resourceName is the identifier at the end of an RDS ARN such as
+// arn:aws:rds:us-east-1:123456789012:db:orders
+func resourceName(arn string) string {
+	if i := strings.LastIndex(arn, ":"); i >= 0 {
+		return arn[i+1:]
+	}
+	return arn
+}
+
+// window returns the maintenance window of the instance or cluster named id
+func (r *MaintenanceReport) window(id string) string {
+	if c, ok := r.Inventory.Cluster(id); ok {
+		return c.MaintenanceWindow
+	}
+	if i, ok := r.Inventory.Instance(id); ok {
+		return i.MaintenanceWindow
+	}
+	return ""
+}
+
+// Format renders the pending actions with when each will be applied,
+// followed by every maintenance window
+func (r *MaintenanceReport) Format() string {
+	var sb strings.Builder
+	if len(r.Actions) == 0 {
+		sb.WriteString("No pending RDS maintenance.\n")
+	} else {
+		fmt.Fprintf(&sb, "Pending RDS maintenance (%d):\n", len(r.Actions))
+		for _, a := range r.Actions {
+			fmt.Fprintf(&sb, "  - %s: %s", a.Resource, a.Action)
+			if a.Description != "" {
+				fmt.Fprintf(&sb, " (%s)", a.Description)
+			}
+			sb.WriteString("\n")
+			var when []string
+			if !a.AutoApplyAfter.IsZero() {
+				window := r.window(a.Resource)
+				if window == "" {
+					window = "the maintenance window"
+				} else {
+					window += " UTC"
+				}
+				when = append(when, fmt.Sprintf("applied in %s after %s", window, a.AutoApplyAfter.UTC().Format(time.DateOnly)))
+			}
+			if !a.ForcedApply.IsZero() {
+				when = append(when, "forced on "+a.ForcedApply.UTC().Format(time.DateOnly))
+			}
+			if a.OptIn != "" {
+				when = append(when, "opted in: "+a.OptIn)
+			}
+			if len(when) == 0 {
+				when = append(when, "optional, not scheduled")
+			}
+			fmt.Fprintf(&sb, "    %s\n", strings.Join(when, "; "))
+		}
+		a := r.Actions[0]
+		fmt.Fprintf(&sb, "\nApply one now instead of waiting for the window with, e.g.:\n  aws rds apply-pending-maintenance-action --resource-identifier %s --apply-action %s --opt-in-type immediate\n", a.ResourceARN, a.Action)
+	}
+
+	var windows []string
+	for _, c := range r.Inventory.Clusters {
+		windows = append(windows, fmt.Sprintf("  %s (cluster): %s", c.ID, orDash(c.MaintenanceWindow)))
+	}
+	for _, i := range r.Inventory.Instances {
+		windows = append(windows, fmt.Sprintf("  %s: %s", i.ID, orDash(i.MaintenanceWindow)))
+	}
+	if len(windows) > 0 {
+		sb.WriteString("\nMaintenance windows (UTC):\n")
+		sb.WriteString(strings.Join(windows, "\n") + "\n")
+	}
+	return sb.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package rds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CloudWatch metrics the listing reports
+const (
+	MetricFreeStorage = "FreeStorageSpace"
+	MetricConnections = "DatabaseConnections"
+	MetricCPU         = "CPUUtilization"
+	// MetricVolumeUsed is the size of an Aurora cluster volume
+	MetricVolumeUsed = "VolumeBytesUsed"
+)
+
+// metricWindow is how far back the listing looks for a datapoint. Aurora
+// reports VolumeBytesUsed less often than the per-instance metrics.
+const metricWindow = 3 * time.Hour
+
+// metricPeriod is the datapoint granularity in seconds
+const metricPeriod = 300
+
+// maxMetricQueries is how many queries one get-metric-data call accepts
+const maxMetricQueries = 500
+
+// metricTarget is one metric of one instance or cluster
+type metricTarget struct {
+	metrics   map[string]float64
+	dimension string
+	id        string
+	name      string
+}
+
+// loadMetrics fills in the latest value of each metric for every instance
+// and Aurora cluster
+func (a *Agent) loadMetrics(ctx context.Context, inv *Inventory, now time.Time) error {
+	var targets []metricTarget
+	for i := range inv.Instances {
+		inst := &inv.Instances[i]
+		inst.Metrics = map[string]float64{}
+		names := []string{MetricConnections, MetricCPU}
+		if !inst.Aurora() {
+			names = append([]string{MetricFreeStorage}, names...)
+		}
+		for _, name := range names {
+			targets = append(targets, metricTarget{inst.Metrics, "DBInstanceIdentifier", inst.ID, name})
+		}
+	}
+	for i := range inv.Clusters {
+		c := &inv.Clusters[i]
+		c.Metrics = map[string]float64{}
+		if c.Aurora() {
+			targets = append(targets, metricTarget{c.Metrics, "DBClusterIdentifier", c.ID, MetricVolumeUsed})
+		}
+	}
+
+	start := now.Add(-metricWindow).UTC().Format(time.RFC3339)
+	end := now.UTC().Format(time.RFC3339)
+	for offset := 0; offset < len(targets); offset += maxMetricQueries {
+		batch := targets[offset:min(offset+maxMetricQueries, len(targets))]
+		values, err := a.metricData(ctx, batch, start, end)
+		if err != nil {
+			return err
+		}
+		for i, t := range batch {
+			if v, ok := values[fmt.Sprintf("m%d", i)]; ok {
+				t.metrics[t.name] = v
+			}
+		}
+	}
+	return nil
+}
+
+// metricData returns the newest value of each target keyed by query ID,
+// m0, m1, ... in the order of targets
+func (a *Agent) metricData(ctx context.Context, targets []metricTarget, start, end string) (map[string]float64, error) {
+	type dimension struct {
+		Name  string `json:"Name"`
+		Value string `json:"Value"`
+	}
+	type query struct {
+		ID         string `json:"Id"`
+		MetricStat struct {
+			Metric struct {
+				Namespace  string      `json:"Namespace"`
+				MetricName string      `json:"MetricName"`
+				Dimensions []dimension `json:"Dimensions"`
+			} `json:"Metric"`
+			Period int    `json:"Period"`
+			Stat   string `json:"Stat"`
+		} `json:"MetricStat"`
+		ReturnData bool `json:"ReturnData"`
+	}
+	queries := make([]query, len(targets))
+	for i, t := range targets {
+		q := &queries[i]
+		q.ID = fmt.Sprintf("m%d", i)
+		q.MetricStat.Metric.Namespace = "AWS/RDS"
+		q.MetricStat.Metric.MetricName = t.name
+		q.MetricStat.Metric.Dimensions = []dimension{{Name: t.dimension, Value: t.id}}
+		q.MetricStat.Period = metricPeriod
+		q.MetricStat.Stat = "Average"
+		q.ReturnData = true
+	}
+	queriesJSON, _ := json.Marshal(queries)
+
+	var resp struct {
+		MetricDataResults []struct {
+			ID     string    `json:"Id"`
+			Values []float64 `json:"Values"`
+		} `json:"MetricDataResults"`
+	}
+	if err := a.runJSON(ctx, &resp, "cloudwatch", "get-metric-data",
+		"--metric-data-queries", string(queriesJSON),
+		"--start-time", start, "--end-time", end,
+		"--scan-by", "TimestampDescending"); err != nil {
+		return nil, fmt.Errorf("failed to read RDS metrics: %w", err)
+	}
+	values := map[string]float64{}
+	for _, r := range resp.MetricDataResults {
+		if len(r.Values) > 0 {
+			values[r.ID] = r.Values[0]
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,325 @@
+package rds
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// maxIdentifierLength is the longest identifier RDS accepts for instances
+// and clusters
+const maxIdentifierLength = 63
+
+// Plan builds the maker plan for a snapshot, restore or resize request. The
+// plan is returned for review; nothing is changed.
+func (a *Agent) Plan(ctx context.Context, req Request, question string, now time.Time) (*maker.Plan, error) {
+	inv, err := a.Describe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return BuildPlan(inv, req, question, now)
+}
+
+// BuildPlan builds the plan for req against the instances and clusters in
+// inv
+func BuildPlan(inv *Inventory, req Request, question string, now time.Time) (*maker.Plan, error) {
+	plan := &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "aws",
+		Question:  question,
+	}
+	var err error
+	switch req.Op {
+	case Snapshot:
+		err = snapshotPlan(plan, inv, req, now)
+	case Restore:
+		err = restorePlan(plan, inv, req, now)
+	case Resize:
+		err = resizePlan(plan, inv, req)
+	default:
+		return nil, fmt.Errorf("nothing to plan for a listing")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// resolve finds the instance or cluster id names, or the only one there is
+// when id is empty. Members of a cluster resolve to the cluster when
+// toCluster is set, since RDS snapshots and restores them as a whole.
+func resolve(inv *Inventory, id, verb string, toCluster bool) (*Instance, *Cluster, error) {
+	if id == "" {
+		standalone := 0
+		var only Instance
+		for _, i := range inv.Instances {
+			if i.Cluster == "" {
+				standalone++
+				only = i
+			}
+		}
+		switch {
+		case standalone == 1 && len(inv.Clusters) == 0:
+			return &only, nil, nil
+		case standalone == 0 && len(inv.Clusters) == 1:
+			c := inv.Clusters[0]
+			return nil, &c, nil
+		}
+		return nil, nil, fmt.Errorf("name the instance or cluster to %s (%s)", verb, inv.names())
+	}
+	if c, ok := inv.Cluster(id); ok {
+		return nil, &c, nil
+	}
+	if i, ok := inv.Instance(id); ok {
+		if toCluster && i.Cluster != "" {
+			if c, ok := inv.Cluster(i.Cluster); ok {
+				return nil, &c, nil
+			}
+		}
+		return &i, nil, nil
+	}
+	return nil, nil, fmt.Errorf("no RDS instance or cluster named %s (%s)", id, inv.names())
+}
+
+// stamp suffixes identifiers the plan creates
+func stamp(now time.Time) string {
+	return now.UTC().Format("20060102-1504")
+}
+
+// derivedIdentifier is source-suffix, shortening source to fit
+func derivedIdentifier(source, suffix string) string {
+	if over := len(source) + 1 + len(suffix) - maxIdentifierLength; over > 0 {
+		source = strings.TrimRight(source[:len(source)-over], "-")
+	}
+	return source + "-" + suffix
+}
+
+func snapshotPlan(plan *maker.Plan, inv *Inventory, req Request, now time.Time) error {
+	inst, cluster, err := resolve(inv, req.Identifier, "snapshot", true)
+	if err != nil {
+		return err
+	}
+	if cluster != nil {
+		snapshot := cluster.ID + "-clanker-" + stamp(now)
+		plan.Summary = fmt.Sprintf("Snapshot DB cluster %s as %s", cluster.ID, snapshot)
+		plan.Commands = []maker.Command{
+			{Args: []string{"rds", "create-db-cluster-snapshot", "--db-cluster-identifier", cluster.ID, "--db-cluster-snapshot-identifier", snapshot},
+				Reason: "Take a manual snapshot of " + cluster.ID},
+			{Args: []string{"rds", "wait", "db-cluster-snapshot-available", "--db-cluster-snapshot-identifier", snapshot},
+				Reason: "Wait for the snapshot to complete"},
+		}
+	} else {
+		snapshot := inst.ID + "-clanker-" + stamp(now)
+		plan.Summary = fmt.Sprintf("Snapshot DB instance %s as %s", inst.ID, snapshot)
+		plan.Commands = []maker.Command{
+			{Args: []string{"rds", "create-db-snapshot", "--db-instance-identifier", inst.ID, "--db-snapshot-identifier", snapshot},
+				Reason: "Take a manual snapshot of " + inst.ID},
+			{Args: []string{"rds", "wait", "db-snapshot-available", "--db-snapshot-identifier", snapshot},
+				Reason: "Wait for the snapshot to complete"},
+		}
+	}
+	plan.Notes = append(plan.Notes, "Manual snapshots are kept until deleted, unlike automated backups, and are billed as backup storage")
+	return nil
+}
+
+// restoreTimeArgs are the restore time flags, with the instance or cluster
+// spelling of the timestamp flag
+func restoreTimeArgs(req Request, timeFlag string) []string {
+	if req.RestoreTime.IsZero() {
+		return []string{"--use-latest-restorable-time"}
+	}
+	return []string{timeFlag, req.RestoreTime.UTC().Format(time.RFC3339)}
+}
+
+// describeRestoreTime is the restore point for summaries
+func describeRestoreTime(req Request) string {
+	if req.RestoreTime.IsZero() {
+		return "its latest restorable time"
+	}
+	return req.RestoreTime.UTC().Format(time.DateTime) + " UTC"
+}
+
+// checkRestoreWindow rejects restore times outside the backups a source
+// keeps
+func checkRestoreWindow(id string, req Request, retention int, earliest, latest, now time.Time) error {
+	if retention == 0 {
+		return fmt.Errorf("%s has automated backups turned off, so it cannot be restored to a point in time", id)
+	}
+	t := req.RestoreTime
+	if t.IsZero() {
+		return nil
+	}
+	if t.After(now) {
+		return fmt.Errorf("restore time %s is in the future", t.UTC().Format(time.DateTime))
+	}
+	if !latest.IsZero() && t.After(latest) {
+		return fmt.Errorf("%s can be restored up to %s UTC; ask for that time or the latest restorable time", id, latest.UTC().Format(time.DateTime))
+	}
+	if earliest.IsZero() {
+		earliest = now.AddDate(0, 0, -retention)
+	}
+	if t.Before(earliest) {
+		return fmt.Errorf("%s can be restored back to %s UTC (%d days of backups)", id, earliest.UTC().Format(time.DateTime), retention)
+	}
+	return nil
+}
+
+func restorePlan(plan *maker.Plan, inv *Inventory, req Request, now time.Time) error {
+	inst, cluster, err := resolve(inv, req.Identifier, "restore", true)
+	if err != nil {
+		return err
+	}
+	source := ""
+	if cluster != nil {
+		source = cluster.ID
+	} else {
+		source = inst.ID
+	}
+	target := req.Target
+	if target == "" {
+		target = derivedIdentifier(source, "restore-"+stamp(now))
+	}
+	if _, ok := inv.Instance(target); ok {
+		return fmt.Errorf("an instance named %s already exists; name the restored copy with \"as <name>\"", target)
+	}
+	if _, ok := inv.Cluster(target); ok {
+		return fmt.Errorf("a cluster named %s already exists; name the restored copy with \"as <name>\"", target)
+	}
+
+	if cluster != nil {
+		if err := checkRestoreWindow(cluster.ID, req, cluster.BackupRetention, cluster.EarliestRestorable, cluster.LatestRestorable, now); err != nil {
+			return err
+		}
+		return restoreClusterPlan(plan, inv, *cluster, target, req)
+	}
+	if err := checkRestoreWindow(inst.ID, req, inst.BackupRetention, time.Time{}, inst.LatestRestorable, now); err != nil {
+		return err
+	}
+
+	args := []string{"rds", "restore-db-instance-to-point-in-time",
+		"--source-db-instance-identifier", inst.ID, "--target-db-instance-identifier", target}
+	args = append(args, restoreTimeArgs(req, "--restore-time")...)
+	args = append(args, "--db-instance-class", inst.Class)
+	if inst.SubnetGroup != "" {
+		args = append(args, "--db-subnet-group-name", inst.SubnetGroup)
+	}
+	if len(inst.SecurityGroups) > 0 {
+		args = append(args, "--vpc-security-group-ids")
+		args = append(args, inst.SecurityGroups...)
+	}
+	if inst.MultiAZ {
+		args = append(args, "--multi-az")
+	}
+	plan.Summary = fmt.Sprintf("Restore DB instance %s to %s as %s", inst.ID, describeRestoreTime(req), target)
+	plan.Commands = []maker.Command{
+		{Args: args, Reason: fmt.Sprintf("Restore %s to %s as a new instance", inst.ID, describeRestoreTime(req))},
+		{Args: []string{"rds", "wait", "db-instance-available", "--db-instance-identifier", target},
+			Reason: "Wait for the restored instance to become available"},
+	}
+	plan.Notes = append(plan.Notes,
+		fmt.Sprintf("The restore creates %s; %s is not changed. Point applications at the new endpoint once the data is verified", target, inst.ID),
+		"The restored instance uses the default parameter and option groups unless they are set afterwards with modify-db-instance",
+	)
+	return nil
+}
+
+func restoreClusterPlan(plan *maker.Plan, inv *Inventory, cluster Cluster, target string, req Request) error {
+	class := ""
+	if writer, ok := inv.Instance(cluster.Writer()); ok {
+		class = writer.Class
+	}
+	if class == "" {
+		return fmt.Errorf("cannot tell the instance class of %s's writer to size the restored cluster", cluster.ID)
+	}
+
+	args := []string{"rds", "restore-db-cluster-to-point-in-time",
+		"--source-db-cluster-identifier", cluster.ID, "--db-cluster-identifier", target}
+	args = append(args, restoreTimeArgs(req, "--restore-to-time")...)
+	if cluster.SubnetGroup != "" {
+		args = append(args, "--db-subnet-group-name", cluster.SubnetGroup)
+	}
+	if len(cluster.SecurityGroups) > 0 {
+		args = append(args, "--vpc-security-group-ids")
+		args = append(args, cluster.SecurityGroups...)
+	}
+	plan.Summary = fmt.Sprintf("Restore DB cluster %s to %s as %s", cluster.ID, describeRestoreTime(req), target)
+	if !cluster.Aurora() {
+		// Multi-AZ DB clusters create their instances as part of the restore
+		args = append(args, "--db-cluster-instance-class", class)
+		plan.Commands = []maker.Command{
+			{Args: args, Reason: fmt.Sprintf("Restore %s to %s as a new cluster", cluster.ID, describeRestoreTime(req))},
+			{Args: []string{"rds", "wait", "db-cluster-available", "--db-cluster-identifier", target},
+				Reason: "Wait for the restored cluster to become available"},
+		}
+	} else {
+		instance := derivedIdentifier(target, "1")
+		plan.Commands = []maker.Command{
+			{Args: args, Reason: fmt.Sprintf("Restore %s to %s as a new cluster", cluster.ID, describeRestoreTime(req))},
+			{Args: []string{"rds", "wait", "db-cluster-available", "--db-cluster-identifier", target},
+				Reason: "Wait for the restored cluster volume to become available"},
+			{Args: []string{"rds", "create-db-instance", "--db-instance-identifier", instance,
+				"--db-cluster-identifier", target, "--engine", cluster.Engine, "--db-instance-class", class},
+				Reason: "Add a writer instance; a restored Aurora cluster has none"},
+			{Args: []string{"rds", "wait", "db-instance-available", "--db-instance-identifier", instance},
+				Reason: "Wait for the writer instance to become available"},
+		}
+	}
+	plan.Notes = append(plan.Notes,
+		fmt.Sprintf("The restore creates %s; %s is not changed. Point applications at the new endpoint once the data is verified", target, cluster.ID),
+	)
+	return nil
+}
+
+func resizePlan(plan *maker.Plan, inv *Inventory, req Request) error {
+	inst, cluster, err := resolve(inv, req.Identifier, "resize", false)
+	if err != nil {
+		return err
+	}
+	if cluster != nil {
+		members := make([]string, len(cluster.Members))
+		for i, m := range cluster.Members {
+			members[i] = m.ID
+		}
+		return fmt.Errorf("%s is a cluster; name the instance to resize (%s)", cluster.ID, strings.Join(members, ", "))
+	}
+	if req.InstanceClass == "" {
+		return fmt.Errorf("name the instance class to move %s to, e.g. db.r6g.large (it is %s now)", inst.ID, inst.Class)
+	}
+	if req.InstanceClass == inst.Class {
+		return fmt.Errorf("%s is already a %s", inst.ID, inst.Class)
+	}
+
+	args := []string{"rds", "modify-db-instance", "--db-instance-identifier", inst.ID, "--db-instance-class", req.InstanceClass}
+	plan.Summary = fmt.Sprintf("Change DB instance %s from %s to %s", inst.ID, inst.Class, req.InstanceClass)
+	if req.ApplyImmediately {
+		plan.Commands = []maker.Command{
+			{Args: append(args, "--apply-immediately"), Reason: fmt.Sprintf("Move %s to %s now", inst.ID, req.InstanceClass)},
+			{Args: []string{"rds", "wait", "db-instance-available", "--db-instance-identifier", inst.ID},
+				Reason: "Wait for the instance to come back"},
+		}
+		if inst.MultiAZ {
+			plan.Notes = append(plan.Notes, "The standby is resized first, then the instance fails over to it, interrupting connections for a minute or two")
+		} else {
+			plan.Notes = append(plan.Notes, inst.ID+" is single-AZ and is unavailable while its class changes, typically several minutes")
+		}
+	} else {
+		plan.Commands = []maker.Command{
+			{Args: append(args, "--no-apply-immediately"), Reason: fmt.Sprintf("Move %s to %s in its next maintenance window", inst.ID, req.InstanceClass)},
+		}
+		window := inst.MaintenanceWindow
+		if window == "" {
+			window = "its maintenance window"
+		} else {
+			window += " UTC"
+		}
+		plan.Notes = append(plan.Notes, fmt.Sprintf("The change waits for %s; ask again with \"now\" to apply it immediately", window))
+	}
+	if inst.Cluster != "" {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("%s is a member of %s; resizing the writer triggers a failover", inst.ID, inst.Cluster))
+	}
+	return nil
+}
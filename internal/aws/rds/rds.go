@@ -0,0 +1,278 @@
+// Package rds answers operational questions about Amazon RDS and Aurora. It
+// lists instances and clusters with their latest storage, connection and CPU
+// metrics, reports pending maintenance and maintenance windows, and turns
+// snapshots, point-in-time restores and instance class changes into maker
+// plans to review and apply rather than running them directly.
+package rds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/aws"
+)
+
+// Agent reads RDS state and builds plans that change it
+type Agent struct {
+	run   aws.Runner
+	debug bool
+}
+
+// NewAgent creates an RDS agent that calls AWS through run
+func NewAgent(run aws.Runner, debug bool) *Agent {
+	return &Agent{run: run, debug: debug}
+}
+
+// Instance is a DB instance, standalone or a member of an Aurora cluster
+type Instance struct {
+	ID            string
+	Engine        string
+	EngineVersion string
+	Class         string
+	Status        string
+	Endpoint      string
+	Port          int
+	MultiAZ       bool
+	AllocatedGB   int
+	// Cluster is the Aurora or Multi-AZ cluster the instance belongs to
+	Cluster           string
+	MaintenanceWindow string
+	BackupRetention   int
+	LatestRestorable  time.Time
+	SubnetGroup       string
+	SecurityGroups    []string
+	// Metrics are the latest CloudWatch values keyed by metric name; a
+	// metric without a recent datapoint is absent
+	Metrics map[string]float64
+}
+
+// Aurora reports whether the instance keeps its data in an Aurora cluster
+// volume rather than its own storage
+func (i Instance) Aurora() bool {
+	return strings.HasPrefix(i.Engine, "aurora")
+}
+
+// Cluster is an Aurora or Multi-AZ DB cluster
+type Cluster struct {
+	ID                 string
+	Engine             string
+	EngineVersion      string
+	Status             string
+	Endpoint           string
+	ReaderEndpoint     string
+	Port               int
+	Members            []Member
+	MaintenanceWindow  string
+	BackupRetention    int
+	EarliestRestorable time.Time
+	LatestRestorable   time.Time
+	SubnetGroup        string
+	SecurityGroups     []string
+	Metrics            map[string]float64
+}
+
+// Aurora reports whether the cluster is an Aurora cluster rather than a
+// Multi-AZ DB cluster
+func (c Cluster) Aurora() bool {
+	return strings.HasPrefix(c.Engine, "aurora")
+}
+
+// Member is an instance of a cluster
+type Member struct {
+	ID     string
+	Writer bool
+}
+
+// Writer returns the ID of the cluster's writer instance, or ""
+func (c Cluster) Writer() string {
+	for _, m := range c.Members {
+		if m.Writer {
+			return m.ID
+		}
+	}
+	return ""
+}
+
+// Inventory is every DB instance and cluster in the region
+type Inventory struct {
+	Instances []Instance
+	Clusters  []Cluster
+	// MetricsErr is set when CloudWatch could not be read; the instances and
+	// clusters are still listed
+	MetricsErr error
+}
+
+// Instance returns the instance named id
+func (inv *Inventory) Instance(id string) (Instance, bool) {
+	for _, i := range inv.Instances {
+		if strings.EqualFold(i.ID, id) {
+			return i, true
+		}
+	}
+	return Instance{}, false
+}
+
+// Cluster returns the cluster named id
+func (inv *Inventory) Cluster(id string) (Cluster, bool) {
+	for _, c := range inv.Clusters {
+		if strings.EqualFold(c.ID, id) {
+			return c, true
+		}
+	}
+	return Cluster{}, false
+}
+
+// names lists every instance and cluster identifier, for error messages
+func (inv *Inventory) names() string {
+	var names []string
+	for _, c := range inv.Clusters {
+		names = append(names, c.ID+" (cluster)")
+	}
+	for _, i := range inv.Instances {
+		if i.Cluster == "" {
+			names = append(names, i.ID)
+		}
+	}
+	if len(names) == 0 {
+		return "none found"
+	}
+	return strings.Join(names, ", ")
+}
+
+// runJSON runs an aws command with JSON output and decodes it into out
+func (a *Agent) runJSON(ctx context.Context, out any, args ...string) error {
+	raw, err := a.run(ctx, append(args, "--output", "json"))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("failed to parse %s %s output: %w", args[0], args[1], err)
+	}
+	return nil
+}
+
+// Describe returns every DB instance and cluster, without metrics
+func (a *Agent) Describe(ctx context.Context) (*Inventory, error) {
+	var instances struct {
+		DBInstances []struct {
+			DBInstanceIdentifier string
+			Engine               string
+			EngineVersion        string
+			DBInstanceClass      string
+			DBInstanceStatus     string
+			Endpoint             struct {
+				Address string
+				Port    int
+			}
+			MultiAZ                    bool
+			AllocatedStorage           int
+			DBClusterIdentifier        string
+			PreferredMaintenanceWindow string
+			BackupRetentionPeriod      int
+			LatestRestorableTime       time.Time
+			DBSubnetGroup              struct {
+				DBSubnetGroupName string
+			}
+			VpcSecurityGroups []struct {
+				VpcSecurityGroupId string
+			}
+		}
+	}
+	if err := a.runJSON(ctx, &instances, "rds", "describe-db-instances"); err != nil {
+		return nil, fmt.Errorf("failed to describe DB instances: %w", err)
+	}
+	var clusters struct {
+		DBClusters []struct {
+			DBClusterIdentifier string
+			Engine              string
+			EngineVersion       string
+			Status              string
+			Endpoint            string
+			ReaderEndpoint      string
+			Port                int
+			DBClusterMembers    []struct {
+				DBInstanceIdentifier string
+				IsClusterWriter      bool
+			}
+			PreferredMaintenanceWindow string
+			BackupRetentionPeriod      int
+			EarliestRestorableTime     time.Time
+			LatestRestorableTime       time.Time
+			DBSubnetGroup              string
+			VpcSecurityGroups          []struct {
+				VpcSecurityGroupId string
+			}
+		}
+	}
+	if err := a.runJSON(ctx, &clusters, "rds", "describe-db-clusters"); err != nil {
+		return nil, fmt.Errorf("failed to describe DB clusters: %w", err)
+	}
+
+	inv := &Inventory{}
+	for _, db := range instances.DBInstances {
+		i := Instance{
+			ID:                db.DBInstanceIdentifier,
+			Engine:            db.Engine,
+			EngineVersion:     db.EngineVersion,
+			Class:             db.DBInstanceClass,
+			Status:            db.DBInstanceStatus,
+			Endpoint:          db.Endpoint.Address,
+			Port:              db.Endpoint.Port,
+			MultiAZ:           db.MultiAZ,
+			AllocatedGB:       db.AllocatedStorage,
+			Cluster:           db.DBClusterIdentifier,
+			MaintenanceWindow: db.PreferredMaintenanceWindow,
+			BackupRetention:   db.BackupRetentionPeriod,
+			LatestRestorable:  db.LatestRestorableTime,
+			SubnetGroup:       db.DBSubnetGroup.DBSubnetGroupName,
+		}
+		for _, sg := range db.VpcSecurityGroups {
+			i.SecurityGroups = append(i.SecurityGroups, sg.VpcSecurityGroupId)
+		}
+		inv.Instances = append(inv.Instances, i)
+	}
+	for _, db := range clusters.DBClusters {
+		c := Cluster{
+			ID:                 db.DBClusterIdentifier,
+			Engine:             db.Engine,
+			EngineVersion:      db.EngineVersion,
+			Status:             db.Status,
+			Endpoint:           db.Endpoint,
+			ReaderEndpoint:     db.ReaderEndpoint,
+			Port:               db.Port,
+			MaintenanceWindow:  db.PreferredMaintenanceWindow,
+			BackupRetention:    db.BackupRetentionPeriod,
+			EarliestRestorable: db.EarliestRestorableTime,
+			LatestRestorable:   db.LatestRestorableTime,
+			SubnetGroup:        db.DBSubnetGroup,
+		}
+		for _, m := range db.DBClusterMembers {
+			c.Members = append(c.Members, Member{ID: m.DBInstanceIdentifier, Writer: m.IsClusterWriter})
+		}
+		for _, sg := range db.VpcSecurityGroups {
+			c.SecurityGroups = append(c.SecurityGroups, sg.VpcSecurityGroupId)
+		}
+		inv.Clusters = append(inv.Clusters, c)
+	}
+	sort.Slice(inv.Instances, func(i, j int) bool { return inv.Instances[i].ID < inv.Instances[j].ID })
+	sort.Slice(inv.Clusters, func(i, j int) bool { return inv.Clusters[i].ID < inv.Clusters[j].ID })
+	if a.debug {
+		fmt.Printf("[aws-rds] found %d instances and %d clusters\n", len(inv.Instances), len(inv.Clusters))
+	}
+	return inv, nil
+}
+
+// List returns every DB instance and cluster with its latest metrics. A
+// CloudWatch failure is recorded in MetricsErr rather than returned.
+func (a *Agent) List(ctx context.Context, now time.Time) (*Inventory, error) {
+	inv, err := a.Describe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inv.MetricsErr = a.loadMetrics(ctx, inv, now)
+	return inv, nil
+}
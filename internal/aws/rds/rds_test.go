@@ -0,0 +1,340 @@
+package rds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     Request
+	}{
+		{"list my rds instances with storage and connections", Request{Op: List}},
+		{"any pending rds maintenance?", Request{Op: Maintenance}},
+		{"take a snapshot of orders-db", Request{Op: Snapshot, Identifier: "orders-db"}},
+		{"snapshot the rds instance billing", Request{Op: Snapshot, Identifier: "billing"}},
+		{"create a snapshot of the aurora cluster analytics", Request{Op: Snapshot, Identifier: "analytics"}},
+		{
+			"restore orders-db to 2 hours ago",
+			Request{Op: Restore, Identifier: "orders-db", RestoreTime: testNow.Add(-2 * time.Hour)},
+		},
+		{
+			"restore rds instance orders-db to point in time 2026-10-14T09:30:00Z as orders-check",
+			Request{Op: Restore, Identifier: "orders-db", RestoreTime: time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC), Target: "orders-check"},
+		},
+		{"restore aurora cluster analytics to the latest restorable time", Request{Op: Restore, Identifier: "analytics"}},
+		{
+			"resize rds instance orders-db to db.r6g.large",
+			Request{Op: Resize, Identifier: "orders-db", InstanceClass: "db.r6g.large"},
+		},
+		{
+			"change the instance class of orders-db to db.m7g.xlarge now",
+			Request{Op: Resize, Identifier: "orders-db", InstanceClass: "db.m7g.xlarge", ApplyImmediately: true},
+		},
+	}
+	for _, tt := range tests {
+		if got := ParseRequest(tt.question, testNow); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestIsRDSQuestion(t *testing.T) {
+	for _, q := range []string{
+		"list rds instances",
+		"show aurora clusters with connection counts",
+		"snapshot the rds instance orders-db",
+		"restore rds orders-db to 30 minutes ago",
+		"upgrade rds instance orders-db to db.r6g.large",
+		"when is the next rds maintenance window",
+	} {
+		if !IsRDSQuestion(q) {
+			t.Errorf("IsRDSQuestion(%q) = false", q)
+		}
+	}
+	for _, q := range []string{
+		"list databases in production",
+		"how much did rds cost last month",
+		"show rds slow query logs",
+		"create an rds postgres instance",
+		"restore the bucket from a snapshot",
+	} {
+		if IsRDSQuestion(q) {
+			t.Errorf("IsRDSQuestion(%q) = true", q)
+		}
+	}
+}
+
+// fakeRDS answers the aws calls the agent makes
+type fakeRDS struct {
+	calls [][]string
+}
+
+const (
+	instancesJSON = `{"DBInstances": [
+		{"DBInstanceIdentifier": "orders-db", "Engine": "postgres", "EngineVersion": "16.3", "DBInstanceClass": "db.t3.medium",
+		 "DBInstanceStatus": "available", "Endpoint": {"Address": "orders-db.abc.rds.amazonaws.com", "Port": 5432},
+		 "MultiAZ": true, "AllocatedStorage": 100, "PreferredMaintenanceWindow": "sun:03:00-sun:03:30",
+		 "BackupRetentionPeriod": 7, "LatestRestorableTime": "2026-10-15T11:55:00.000000+00:00",
+		 "DBSubnetGroup": {"DBSubnetGroupName": "private"}, "VpcSecurityGroups": [{"VpcSecurityGroupId": "sg-1"}]},
+		{"DBInstanceIdentifier": "analytics-1", "Engine": "aurora-postgresql", "EngineVersion": "16.2", "DBInstanceClass": "db.r6g.large",
+		 "DBInstanceStatus": "available", "Endpoint": {"Address": "analytics-1.abc.rds.amazonaws.com", "Port": 5432},
+		 "DBClusterIdentifier": "analytics", "PreferredMaintenanceWindow": "mon:04:00-mon:04:30", "BackupRetentionPeriod": 1}
+	]}`
+	clustersJSON = `{"DBClusters": [
+		{"DBClusterIdentifier": "analytics", "Engine": "aurora-postgresql", "EngineVersion": "16.2", "Status": "available",
+		 "Endpoint": "analytics.cluster-abc.rds.amazonaws.com", "Port": 5432,
+		 "DBClusterMembers": [{"DBInstanceIdentifier": "analytics-1", "IsClusterWriter": true}],
+		 "PreferredMaintenanceWindow": "mon:04:00-mon:04:30", "BackupRetentionPeriod": 1,
+		 "EarliestRestorableTime": "2026-10-14T12:00:00Z", "LatestRestorableTime": "2026-10-15T11:58:00Z",
+		 "DBSubnetGroup": "private", "VpcSecurityGroups": [{"VpcSecurityGroupId": "sg-2"}]}
+	]}`
+)
+
+func (f *fakeRDS) run(ctx context.Context, args []string) (string, error) {
+	f.calls = append(f.calls, args)
+	switch strings.Join(args[:2], " ") {
+	case "rds describe-db-instances":
+		return instancesJSON, nil
+	case "rds describe-db-clusters":
+		return clustersJSON, nil
+	case "cloudwatch get-metric-data":
+		var queries []struct {
+			ID         string `json:"Id"`
+			MetricStat struct {
+				Metric struct {
+					MetricName string
+					Dimensions []struct{ Value string }
+				}
+			}
+		}
+		if err := json.Unmarshal([]byte(args[slices.Index(args, "--metric-data-queries")+1]), &queries); err != nil {
+			return "", err
+		}
+		values := map[string]string{
+			"orders-db/FreeStorageSpace":    "[5368709120, 6442450944]",
+			"orders-db/DatabaseConnections": "[42]",
+			"orders-db/CPUUtilization":      "[12.5]",
+			"analytics/VolumeBytesUsed":     "[21474836480]",
+		}
+		var results []string
+		for _, q := range queries {
+			v, ok := values[q.MetricStat.Metric.Dimensions[0].Value+"/"+q.MetricStat.Metric.MetricName]
+			if !ok {
+				v = "[]"
+			}
+			results = append(results, `{"Id": "`+q.ID+`", "Values": `+v+`}`)
+		}
+		return `{"MetricDataResults": [` + strings.Join(results, ",") + `]}`, nil
+	case "rds describe-pending-maintenance-actions":
+		return `{"PendingMaintenanceActions": [
+			{"ResourceIdentifier": "arn:aws:rds:us-east-1:123456789012:db:orders-db", "PendingMaintenanceActionDetails": [
+				{"Action": "system-update", "Description": "New Operating System update is available",
+				 "AutoAppliedAfterDate": "2026-10-20T00:00:00Z", "ForcedApplyDate": "2026-11-01T00:00:00Z"}]}
+		]}`, nil
+	}
+	return "", errors.New("unexpected call: " + strings.Join(args, " "))
+}
+
+func TestListWithMetrics(t *testing.T) {
+	aws := &fakeRDS{}
+	inv, err := NewAgent(aws.run, false).List(context.Background(), testNow)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if inv.MetricsErr != nil {
+		t.Fatalf("MetricsErr: %v", inv.MetricsErr)
+	}
+	orders, _ := inv.Instance("orders-db")
+	if orders.Metrics[MetricFreeStorage] != 5368709120 || orders.Metrics[MetricConnections] != 42 {
+		t.Errorf("orders-db metrics = %v", orders.Metrics)
+	}
+	if aurora, _ := inv.Instance("analytics-1"); len(aurora.Metrics) != 0 {
+		t.Errorf("analytics-1 metrics = %v", aurora.Metrics)
+	}
+
+	out := inv.Format()
+	for _, want := range []string{
+		"DB clusters (1):",
+		"analytics-1*",
+		"20.0 GiB",
+		"DB instances (2):",
+		"postgres 16.3 (multi-AZ)",
+		"5.0 GiB (5%)",
+		"12.5%",
+		"orders-db.abc.rds.amazonaws.com:5432",
+		"cluster analytics",
+		"Low on storage:\n  - orders-db has 5% of 100 GiB free",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestListWithoutMetrics(t *testing.T) {
+	run := func(ctx context.Context, args []string) (string, error) {
+		if args[0] == "cloudwatch" {
+			return "", errors.New("AccessDenied")
+		}
+		return (&fakeRDS{}).run(ctx, args)
+	}
+	inv, err := NewAgent(run, false).List(context.Background(), testNow)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if out := inv.Format(); !strings.Contains(out, "Metrics unavailable: failed to read RDS metrics: AccessDenied") {
+		t.Errorf("output:\n%s", out)
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	report, err := NewAgent((&fakeRDS{}).run, false).Maintenance(context.Background())
+	if err != nil {
+		t.Fatalf("Maintenance: %v", err)
+	}
+	out := report.Format()
+	for _, want := range []string{
+		"Pending RDS maintenance (1):",
+		"orders-db: system-update (New Operating System update is available)",
+		"applied in sun:03:00-sun:03:30 UTC after 2026-10-20; forced on 2026-11-01",
+		"--resource-identifier arn:aws:rds:us-east-1:123456789012:db:orders-db --apply-action system-update --opt-in-type immediate",
+		"analytics (cluster): mon:04:00-mon:04:30",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func testInventory(t *testing.T) *Inventory {
+	t.Helper()
+	inv, err := NewAgent((&fakeRDS{}).run, false).Describe(context.Background())
+	if err != nil {
+		t.Fatalf("Describe: %v", err)
+	}
+	return inv
+}
+
+func TestSnapshotPlan(t *testing.T) {
+	inv := testInventory(t)
+	plan, err := BuildPlan(inv, Request{Op: Snapshot, Identifier: "orders-db"}, "q", testNow)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	want := []string{"rds", "create-db-snapshot", "--db-instance-identifier", "orders-db", "--db-snapshot-identifier", "orders-db-clanker-20261015-1200"}
+	if !slices.Equal(plan.Commands[0].Args, want) || plan.Commands[1].Args[1] != "wait" {
+		t.Errorf("commands = %v", plan.Commands)
+	}
+
+	// An Aurora member is snapshotted through its cluster
+	plan, err = BuildPlan(inv, Request{Op: Snapshot, Identifier: "analytics-1"}, "q", testNow)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if plan.Commands[0].Args[1] != "create-db-cluster-snapshot" || plan.Commands[0].Args[3] != "analytics" {
+		t.Errorf("commands = %v", plan.Commands)
+	}
+
+	if _, err := BuildPlan(inv, Request{Op: Snapshot}, "q", testNow); err == nil || !strings.Contains(err.Error(), "analytics (cluster), orders-db") {
+		t.Errorf("ambiguous snapshot err = %v", err)
+	}
+	if _, err := BuildPlan(inv, Request{Op: Snapshot, Identifier: "nope"}, "q", testNow); err == nil || !strings.Contains(err.Error(), "no RDS instance or cluster named nope") {
+		t.Errorf("unknown snapshot err = %v", err)
+	}
+}
+
+func TestRestorePlan(t *testing.T) {
+	inv := testInventory(t)
+	at := testNow.Add(-2 * time.Hour)
+	plan, err := BuildPlan(inv, Request{Op: Restore, Identifier: "orders-db", RestoreTime: at}, "q", testNow)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	want := []string{"rds", "restore-db-instance-to-point-in-time",
+		"--source-db-instance-identifier", "orders-db", "--target-db-instance-identifier", "orders-db-restore-20261015-1200",
+		"--restore-time", "2026-10-15T10:00:00Z", "--db-instance-class", "db.t3.medium",
+		"--db-subnet-group-name", "private", "--vpc-security-group-ids", "sg-1", "--multi-az"}
+	if !slices.Equal(plan.Commands[0].Args, want) {
+		t.Errorf("restore args = %v", plan.Commands[0].Args)
+	}
+	if plan.Summary != "Restore DB instance orders-db to 2026-10-15 10:00:00 UTC as orders-db-restore-20261015-1200" {
+		t.Errorf("summary = %q", plan.Summary)
+	}
+
+	plan, err = BuildPlan(inv, Request{Op: Restore, Identifier: "analytics", Target: "analytics-check"}, "q", testNow)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	var verbs []string
+	for _, c := range plan.Commands {
+		verbs = append(verbs, c.Args[1])
+	}
+	if !slices.Equal(verbs, []string{"restore-db-cluster-to-point-in-time", "wait", "create-db-instance", "wait"}) ||
+		!slices.Contains(plan.Commands[0].Args, "--use-latest-restorable-time") ||
+		!slices.Contains(plan.Commands[2].Args, "db.r6g.large") {
+		t.Errorf("cluster restore commands = %v", plan.Commands)
+	}
+
+	for _, tt := range []struct {
+		req  Request
+		want string
+	}{
+		{Request{Op: Restore, Identifier: "orders-db", RestoreTime: testNow.Add(-time.Minute)}, "can be restored up to 2026-10-15 11:55:00"},
+		{Request{Op: Restore, Identifier: "analytics", RestoreTime: testNow.Add(-48 * time.Hour)}, "can be restored back to 2026-10-14 12:00:00"},
+		{Request{Op: Restore, Identifier: "orders-db", Target: "analytics"}, "a cluster named analytics already exists"},
+	} {
+		if _, err := BuildPlan(inv, tt.req, "q", testNow); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("BuildPlan(%+v) err = %v, want %q", tt.req, err, tt.want)
+		}
+	}
+}
+
+func TestResizePlan(t *testing.T) {
+	inv := testInventory(t)
+	plan, err := BuildPlan(inv, Request{Op: Resize, Identifier: "orders-db", InstanceClass: "db.r6g.large"}, "q", testNow)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if len(plan.Commands) != 1 || !slices.Contains(plan.Commands[0].Args, "--no-apply-immediately") ||
+		!strings.Contains(plan.Notes[0], "sun:03:00-sun:03:30 UTC") {
+		t.Errorf("deferred plan = %+v", plan)
+	}
+
+	plan, err = BuildPlan(inv, Request{Op: Resize, Identifier: "orders-db", InstanceClass: "db.r6g.large", ApplyImmediately: true}, "q", testNow)
+	if err != nil {
+		t.Fatalf("BuildPlan: %v", err)
+	}
+	if len(plan.Commands) != 2 || !slices.Contains(plan.Commands[0].Args, "--apply-immediately") || !strings.Contains(plan.Notes[0], "fails over") {
+		t.Errorf("immediate plan = %+v", plan)
+	}
+
+	for _, tt := range []struct {
+		req  Request
+		want string
+	}{
+		{Request{Op: Resize, Identifier: "analytics", InstanceClass: "db.r6g.xlarge"}, "analytics is a cluster; name the instance to resize (analytics-1)"},
+		{Request{Op: Resize, Identifier: "orders-db"}, "it is db.t3.medium now"},
+		{Request{Op: Resize, Identifier: "orders-db", InstanceClass: "db.t3.medium"}, "already a db.t3.medium"},
+	} {
+		if _, err := BuildPlan(inv, tt.req, "q", testNow); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("BuildPlan(%+v) err = %v, want %q", tt.req, err, tt.want)
+		}
+	}
+}
+
+func TestDerivedIdentifier(t *testing.T) {
+	got := derivedIdentifier(strings.Repeat("a", 60)+"-b", "restore-20261015-1200")
+	if len(got) > maxIdentifierLength || !strings.HasSuffix(got, "-restore-20261015-1200") || strings.Contains(got, "--") {
+		t.Errorf("derivedIdentifier = %q (%d)", got, len(got))
+	}
+}
@@ -0,0 +1,161 @@
+package rds
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operation is what a question asks of RDS
+type Operation int
+
+const (
+	// List shows instances and clusters with their metrics
+	List Operation = iota + 1
+	// Maintenance shows pending maintenance and maintenance windows
+	Maintenance
+	// Snapshot plans a manual snapshot
+	Snapshot
+	// Restore plans a point-in-time restore to a new instance or cluster
+	Restore
+	// Resize plans an instance class change
+	Resize
+)
+
+// Request is a question translated into an RDS operation
+type Request struct {
+	Op Operation
+	// Identifier is the instance or cluster the operation applies to
+	Identifier string
+	// RestoreTime is the point to restore to; zero means the latest
+	// restorable time
+	RestoreTime time.Time
+	// Target names the instance or cluster a restore creates
+	Target           string
+	InstanceClass    string
+	ApplyImmediately bool
+}
+
+var (
+	rdsRe         = regexp.MustCompile(`(?i)\b(?:rds|aurora|db instances?|db clusters?|db snapshots?)\b`)
+	maintenanceRe = regexp.MustCompile(`(?i)\bmaintenance\b`)
+	snapshotRe    = regexp.MustCompile(`(?i)\b(?:create|take|make)\s+(?:an?\s+)?(?:new\s+|manual\s+|fresh\s+)?(?:db\s+|cluster\s+)?snapshot\b|^\s*snapshot\s`)
+	restoreRe     = regexp.MustCompile(`(?i)\brestore\b`)
+	pitrRe        = regexp.MustCompile(`(?i)point[- ]in[- ]time|\bpitr\b|\blatest\b|\bas of\b|\bago\b`)
+	resizeRe      = regexp.MustCompile(`(?i)\b(?:resize|modify|change|switch|move|scale\s+(?:up|down)|upgrade|downgrade)\b`)
+	classRe       = regexp.MustCompile(`\bdb\.[a-z0-9]+\.[a-z0-9]+\b`)
+	classWordsRe  = regexp.MustCompile(`(?i)\binstance (?:class|type|size)\b`)
+	listRe        = regexp.MustCompile(`(?i)\b(?:list|show|what|which|how many|status|storage|disk|connections?|metrics|cpu|overview|describe|instances|clusters)\b`)
+	notRDSRe      = regexp.MustCompile(`(?i)\b(?:cost|costs|spend|spending|bill|billing|price|pricing|logs?|slow query)\b`)
+	immediateRe   = regexp.MustCompile(`(?i)\b(?:now|immediately|right away|asap)\b`)
+
+	agoRe       = regexp.MustCompile(`(?i)\b(\d+)\s*(minutes?|mins?|hours?|hrs?|days?)\s+ago\b`)
+	timestampRe = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2})?(?:Z|[+-]\d{2}:?\d{2})?`)
+
+	identifierRes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\b(?:(?:db|rds|aurora)\s+)?(?:instance|cluster|database)\s+(?:named\s+|called\s+)?["'` + "`" + `]?([a-z][a-z0-9-]*)`),
+		regexp.MustCompile(`(?i)\b(?:of|for|on)\s+(?:the\s+|my\s+)?(?:rds\s+|aurora\s+)?([a-z][a-z0-9-]*)`),
+		regexp.MustCompile(`(?i)\b(?:snapshot|restore|resize|modify|upgrade|downgrade)\s+(?:the\s+|my\s+)?(?:rds\s+|aurora\s+)?([a-z][a-z0-9-]*)`),
+	}
+	targetRe = regexp.MustCompile(`(?i)\b(?:as|into)\s+(?:a\s+new\s+(?:instance|cluster|database)\s+)?(?:named\s+|called\s+)?["'` + "`" + `]?([a-z][a-z0-9-]*)`)
+)
+
+// notIdentifiers are words the identifier patterns can land on that never
+// name an instance
+var notIdentifiers = map[string]bool{
+	"rds": true, "aurora": true, "db": true, "database": true, "databases": true,
+	"instance": true, "instances": true, "cluster": true, "clusters": true,
+	"snapshot": true, "snapshots": true, "class": true, "type": true, "size": true,
+	"the": true, "a": true, "an": true, "my": true, "our": true, "it": true, "this": true, "that": true,
+	"to": true, "from": true, "of": true, "for": true, "on": true, "in": true, "with": true,
+	"new": true, "manual": true, "point": true, "time": true, "latest": true, "pitr": true,
+	"maintenance": true, "now": true, "immediately": true, "and": true, "all": true,
+	"named": true, "called": true, "writer": true, "primary": true,
+}
+
+// ParseRequest reads an RDS operation from question. Questions that ask for
+// no change are listings.
+func ParseRequest(question string, now time.Time) Request {
+	req := Request{Op: List}
+	class := classRe.FindString(question)
+	// The class would otherwise read as an instance named "db"
+	rest := classRe.ReplaceAllString(question, " ")
+
+	switch {
+	case maintenanceRe.MatchString(question) && class == "":
+		req.Op = Maintenance
+	case snapshotRe.MatchString(question) && !restoreRe.MatchString(question):
+		req.Op = Snapshot
+	case restoreRe.MatchString(question) && (pitrRe.MatchString(question) || timestampRe.MatchString(question)):
+		req.Op = Restore
+		req.RestoreTime = parseRestoreTime(question, now)
+		if m := targetRe.FindStringSubmatch(rest); m != nil && !notIdentifiers[strings.ToLower(m[1])] {
+			req.Target = strings.ToLower(m[1])
+			rest = strings.Replace(rest, m[0], " ", 1)
+		}
+	case resizeRe.MatchString(question) && (class != "" || classWordsRe.MatchString(question)):
+		req.Op = Resize
+		req.InstanceClass = class
+		req.ApplyImmediately = immediateRe.MatchString(question)
+	}
+	if req.Op != List && req.Op != Maintenance {
+		req.Identifier = parseIdentifier(rest)
+	}
+	return req
+}
+
+// parseIdentifier returns the first word the identifier patterns find that
+// could name an instance or cluster
+func parseIdentifier(question string) string {
+	for _, re := range identifierRes {
+		for _, m := range re.FindAllStringSubmatch(question, -1) {
+			if id := strings.ToLower(m[1]); !notIdentifiers[id] {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// parseRestoreTime reads "2 hours ago" or a timestamp from question; zero
+// means the latest restorable time
+func parseRestoreTime(question string, now time.Time) time.Time {
+	if m := agoRe.FindStringSubmatch(question); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := time.Minute
+		switch strings.ToLower(m[2])[0] {
+		case 'h':
+			unit = time.Hour
+		case 'd':
+			unit = 24 * time.Hour
+		}
+		return now.Add(-time.Duration(n) * unit).UTC().Truncate(time.Second)
+	}
+	if s := timestampRe.FindString(question); s != "" {
+		s = strings.Replace(s, " ", "T", 1)
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02T15:04:05Z0700", "2006-01-02T15:04Z0700"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.UTC()
+			}
+		}
+		for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+			if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// IsRDSQuestion reports whether question is about RDS or Aurora instances,
+// clusters, snapshots or maintenance rather than spend or logs
+func IsRDSQuestion(question string) bool {
+	if !rdsRe.MatchString(question) || notRDSRe.MatchString(question) {
+		return false
+	}
+	if ParseRequest(question, time.Now()).Op != List {
+		return true
+	}
+	return listRe.MatchString(question)
+}
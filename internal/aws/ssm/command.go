@@ -0,0 +1,251 @@
+package ssm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/dryrun"
+	"golang.org/x/sync/errgroup"
+)
+
+// runShellDocument is the SSM document that runs shell commands on Linux
+const runShellDocument = "AWS-RunShellScript"
+
+// outputConcurrency bounds the per-instance get-command-invocation calls
+const outputConcurrency = 8
+
+// pollAttempts and pollDelay bound the wait for a command to finish on
+// every instance
+var (
+	pollAttempts = 150
+	pollDelay    = 2 * time.Second
+)
+
+// terminalStatuses are the invocation states a command does not leave
+var terminalStatuses = map[string]bool{
+	"Success":       true,
+	"Failed":        true,
+	"TimedOut":      true,
+	"Cancelled":     true,
+	"Undeliverable": true,
+	"Terminated":    true,
+}
+
+// Invocation is the outcome of a command on one instance
+type Invocation struct {
+	Instance Instance
+	Status   string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// RunResult is the outcome of a command across instances
+type RunResult struct {
+	Command     string
+	CommandIDs  []string
+	Invocations []Invocation
+	// Skipped are matching instances SSM cannot reach
+	Skipped []Instance
+}
+
+// Run runs command as a shell script on every managed instance, at most
+// maxFilterValues per send-command, and waits for each instance's output.
+// Instances whose SSM agent is offline are listed in Skipped. A dry run
+// prints each send-command and returns dryrun.ErrStopped without sending.
+func (a *Agent) Run(ctx context.Context, instances []Instance, command string) (*RunResult, error) {
+	result := &RunResult{Command: command}
+	var targets []Instance
+	for _, i := range instances {
+		if i.Managed {
+			targets = append(targets, i)
+		} else {
+			result.Skipped = append(result.Skipped, i)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("none of the %d matching instances has an online SSM agent", len(instances))
+	}
+
+	if dryrun.Enabled() {
+		for start := 0; start < len(targets); start += maxFilterValues {
+			batch := targets[start:min(start+maxFilterValues, len(targets))]
+			dryrun.Print(os.Stdout, "aws", sendArgs(batch, command))
+		}
+		return nil, dryrun.ErrStopped
+	}
+
+	for start := 0; start < len(targets); start += maxFilterValues {
+		batch := targets[start:min(start+maxFilterValues, len(targets))]
+		commandID, err := a.send(ctx, batch, command)
+		if err != nil {
+			return nil, err
+		}
+		result.CommandIDs = append(result.CommandIDs, commandID)
+		if err := a.wait(ctx, commandID, len(batch)); err != nil {
+			return nil, err
+		}
+		result.Invocations = append(result.Invocations, a.collect(ctx, commandID, batch)...)
+	}
+	return result, nil
+}
+
+func (a *Agent) send(ctx context.Context, targets []Instance, command string) (string, error) {
+	args := sendArgs(targets, command)
+	out, err := a.run(ctx, args)
+	audit.RecordStep("aws", args, err)
+	if err != nil {
+		return "", fmt.Errorf("failed to send command: %w", err)
+	}
+	var resp struct {
+		Command struct {
+			CommandID string `json:"CommandId"`
+		} `json:"Command"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil || resp.Command.CommandID == "" {
+		return "", fmt.Errorf("failed to parse send-command output: %s", strings.TrimSpace(out))
+	}
+	if a.debug {
+		fmt.Printf("[aws-ssm] sent command %s to %d instances\n", resp.Command.CommandID, len(targets))
+	}
+	return resp.Command.CommandID, nil
+}
+
+// sendArgs are the aws arguments that run command on targets
+func sendArgs(targets []Instance, command string) []string {
+	params, _ := json.Marshal(map[string][]string{"commands": {command}})
+	args := []string{"ssm", "send-command", "--document-name", runShellDocument, "--instance-ids"}
+	for _, t := range targets {
+		args = append(args, t.ID)
+	}
+	return append(args,
+		"--parameters", string(params),
+		"--comment", commandComment(command),
+		"--output", "json",
+	)
+}
+
+// commandComment labels the command in the SSM console; comments are
+// limited to 100 characters
+func commandComment(command string) string {
+	comment := "clanker: " + strings.Join(strings.Fields(command), " ")
+	if r := []rune(comment); len(r) > 100 {
+		comment = string(r[:97]) + "..."
+	}
+	return comment
+}
+
+// wait polls until the command reaches a final state on all n instances
+func (a *Agent) wait(ctx context.Context, commandID string, n int) error {
+	for attempt := 0; attempt < pollAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(pollDelay):
+			}
+		}
+		out, err := a.run(ctx, []string{"ssm", "list-command-invocations", "--command-id", commandID, "--output", "json"})
+		if err != nil {
+			return fmt.Errorf("failed to check command status: %w", err)
+		}
+		var resp struct {
+			CommandInvocations []struct {
+				Status string `json:"Status"`
+			} `json:"CommandInvocations"`
+		}
+		if err := json.Unmarshal([]byte(out), &resp); err != nil {
+			return fmt.Errorf("failed to parse command status: %w", err)
+		}
+		done := 0
+		for _, inv := range resp.CommandInvocations {
+			if terminalStatuses[inv.Status] {
+				done++
+			}
+		}
+		if done >= n {
+			return nil
+		}
+	}
+	return fmt.Errorf("command %s did not finish on every instance; check it with aws ssm list-command-invocations --command-id %s", commandID, commandID)
+}
+
+// collect reads each instance's output. get-command-invocation returns up
+// to 24,000 characters of stdout, more than list-command-invocations does.
+// An instance whose output cannot be read reports status Unknown.
+func (a *Agent) collect(ctx context.Context, commandID string, targets []Instance) []Invocation {
+	invocations := make([]Invocation, len(targets))
+	var g errgroup.Group
+	g.SetLimit(outputConcurrency)
+	for i, t := range targets {
+		g.Go(func() error {
+			invocations[i] = Invocation{Instance: t}
+			out, err := a.run(ctx, []string{"ssm", "get-command-invocation",
+				"--command-id", commandID, "--instance-id", t.ID, "--output", "json"})
+			if err != nil {
+				invocations[i].Status = "Unknown"
+				invocations[i].Stderr = err.Error()
+				return nil
+			}
+			var resp struct {
+				Status                string `json:"Status"`
+				ResponseCode          int    `json:"ResponseCode"`
+				StandardOutputContent string `json:"StandardOutputContent"`
+				StandardErrorContent  string `json:"StandardErrorContent"`
+			}
+			if err := json.Unmarshal([]byte(out), &resp); err != nil {
+				invocations[i].Status = "Unknown"
+				invocations[i].Stderr = fmt.Sprintf("failed to parse command output: %v", err)
+				return nil
+			}
+			invocations[i].Status = resp.Status
+			invocations[i].ExitCode = resp.ResponseCode
+			invocations[i].Stdout = resp.StandardOutputContent
+			invocations[i].Stderr = resp.StandardErrorContent
+			return nil
+		})
+	}
+	_ = g.Wait()
+	return invocations
+}
+
+// Succeeded counts the instances the command succeeded on
+func (r *RunResult) Succeeded() int {
+	n := 0
+	for _, inv := range r.Invocations {
+		if inv.Status == "Success" {
+			n++
+		}
+	}
+	return n
+}
+
+// Format renders each instance's output under a header with its status,
+// followed by a summary
+func (r *RunResult) Format() string {
+	var sb strings.Builder
+	for _, inv := range r.Invocations {
+		fmt.Fprintf(&sb, "=== %s: %s, exit %d ===\n", inv.Instance.Label(), inv.Status, inv.ExitCode)
+		if out := strings.TrimRight(inv.Stdout, "\n"); out != "" {
+			sb.WriteString(out + "\n")
+		}
+		if errOut := strings.TrimRight(inv.Stderr, "\n"); errOut != "" {
+			fmt.Fprintf(&sb, "[stderr]\n%s\n", errOut)
+		}
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "Ran %q on %d instances: %d succeeded, %d failed.\n",
+		r.Command, len(r.Invocations), r.Succeeded(), len(r.Invocations)-r.Succeeded())
+	if len(r.Skipped) > 0 {
+		sb.WriteString("Skipped (SSM agent not online):\n")
+		for _, i := range r.Skipped {
+			fmt.Fprintf(&sb, "  - %s\n", i.Label())
+		}
+	}
+	return sb.String()
+}
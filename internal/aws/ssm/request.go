@@ -0,0 +1,88 @@
+package ssm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Action is what a question asks SSM to do
+type Action int
+
+const (
+	// Connect opens a Session Manager shell on one instance
+	Connect Action = iota + 1
+	// RunCommand runs a shell command on every matching instance
+	RunCommand
+)
+
+// Request is a question translated into an SSM action
+type Request struct {
+	Action   Action
+	Selector Selector
+	// Name is the Name tag of the instance to connect to when the question
+	// gives a name rather than an ID
+	Name    string
+	Command string
+}
+
+var (
+	instanceIDRe  = regexp.MustCompile(`\bi-[0-9a-f]{8,17}\b`)
+	connectRe     = regexp.MustCompile(`(?i)\b(?:connect|ssh|shell|session|log ?in(?:to)?|login)\b`)
+	runRe         = regexp.MustCompile(`(?i)\b(?:run|execute|exec)\b`)
+	tagRe         = regexp.MustCompile(`(?i)\btag(?:ged)?\s+(?:with\s+)?([\w.:/+@\-]+)\s*[=:]\s*([\w.:/+@\-]+)`)
+	clusterRe     = regexp.MustCompile(`(?i)\b(?:nodes?|instances?|machines?)\s+(?:of|in)\s+(?:the\s+)?(?:kubeadm\s+)?cluster\s+([\w\-]+)`)
+	quotedCmdRe   = regexp.MustCompile("`([^`]+)`|\"([^\"]+)\"|'([^']+)'")
+	bareCmdRe     = regexp.MustCompile(`(?i)\b(?:run|execute|exec)\s+(?:the\s+command\s+)?(.+?)\s+on\s+(?:all\s+|every\s+|each\s+|the\s+)?(?:instances?|nodes?|servers?|machines?|hosts?|i-)`)
+	connectNameRe = regexp.MustCompile(`(?i)\b(?:connect|ssh|shell|session|log ?in(?:to)?|login)\s+(?:to|into|on)\s+(?:the\s+)?(?:ec2\s+)?(?:instance|node|server|machine|host)\s+(?:named\s+|called\s+)?["']?([\w.\-]+)`)
+)
+
+// ParseRequest reads an SSM action from question, such as "connect to
+// instance i-0abc" or "run `uptime` on all instances tagged role=web". It
+// reports false when the question asks for neither.
+func ParseRequest(question string) (Request, bool) {
+	var sel Selector
+	sel.InstanceIDs = instanceIDRe.FindAllString(question, -1)
+	for _, m := range tagRe.FindAllStringSubmatch(question, -1) {
+		sel.Tags = append(sel.Tags, Tag{Key: m[1], Value: m[2]})
+	}
+	if m := clusterRe.FindStringSubmatch(question); m != nil {
+		sel.Cluster = m[1]
+	}
+
+	if runRe.MatchString(question) && !sel.Empty() {
+		if command := parseCommand(question); command != "" {
+			return Request{Action: RunCommand, Selector: sel, Command: command}, true
+		}
+	}
+	if !connectRe.MatchString(question) {
+		return Request{}, false
+	}
+	if len(sel.InstanceIDs) == 1 {
+		return Request{Action: Connect, Selector: Selector{InstanceIDs: sel.InstanceIDs}}, true
+	}
+	if len(sel.InstanceIDs) == 0 {
+		if m := connectNameRe.FindStringSubmatch(question); m != nil {
+			return Request{Action: Connect, Name: m[1], Selector: Selector{Tags: []Tag{{Key: "Name", Value: m[1]}}}}, true
+		}
+	}
+	return Request{}, false
+}
+
+// parseCommand returns the quoted command in question, or the words between
+// "run" and "on <instances>"
+func parseCommand(question string) string {
+	if m := quotedCmdRe.FindStringSubmatch(question); m != nil {
+		return strings.TrimSpace(m[1] + m[2] + m[3])
+	}
+	if m := bareCmdRe.FindStringSubmatch(question); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return ""
+}
+
+// IsSSMQuestion reports whether question asks to connect to an instance or
+// run a command on instances through SSM
+func IsSSMQuestion(question string) bool {
+	_, ok := ParseRequest(question)
+	return ok
+}
@@ -0,0 +1,224 @@
+// Package ssm reaches EC2 instances through AWS Systems Manager instead of
+// SSH. It opens Session Manager shells and runs shell commands with
+// SendCommand on every instance matching an ID, tags or a kubeadm cluster,
+// collecting each instance's output. No inbound port or key pair is needed,
+// only the SSM agent and an instance profile that allows it.
+package ssm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/aws"
+)
+
+// maxFilterValues is how many instance IDs one describe call accepts in a
+// filter, and how many targets one send-command accepts
+const maxFilterValues = 50
+
+// Agent finds instances and runs commands on them through SSM
+type Agent struct {
+	run   aws.Runner
+	debug bool
+}
+
+// NewAgent creates an SSM agent that calls AWS through run
+func NewAgent(run aws.Runner, debug bool) *Agent {
+	return &Agent{run: run, debug: debug}
+}
+
+// Tag is an EC2 tag an instance must carry
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// ParseTag reads a tag written as key=value
+func ParseTag(s string) (Tag, error) {
+	key, value, ok := strings.Cut(s, "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !ok || key == "" || value == "" {
+		return Tag{}, fmt.Errorf("invalid tag %q: use key=value", s)
+	}
+	return Tag{Key: key, Value: value}, nil
+}
+
+func (t Tag) String() string {
+	return t.Key + "=" + t.Value
+}
+
+// Selector picks running instances by ID, by tag, or as the nodes of a
+// kubeadm cluster clanker created. The conditions are combined.
+type Selector struct {
+	InstanceIDs []string
+	Tags        []Tag
+	Cluster     string
+}
+
+// Empty reports whether the selector would match every instance
+func (s Selector) Empty() bool {
+	return len(s.InstanceIDs) == 0 && len(s.Tags) == 0 && s.Cluster == ""
+}
+
+func (s Selector) String() string {
+	var parts []string
+	if len(s.InstanceIDs) > 0 {
+		parts = append(parts, strings.Join(s.InstanceIDs, ", "))
+	}
+	if len(s.Tags) > 0 {
+		tags := make([]string, len(s.Tags))
+		for i, t := range s.Tags {
+			tags[i] = t.String()
+		}
+		parts = append(parts, "tagged "+strings.Join(tags, ", "))
+	}
+	if s.Cluster != "" {
+		parts = append(parts, "in kubeadm cluster "+s.Cluster)
+	}
+	if len(parts) == 0 {
+		return "all instances"
+	}
+	return strings.Join(parts, " ")
+}
+
+// Instance is a running EC2 instance and whether SSM can reach it
+type Instance struct {
+	ID        string
+	Name      string
+	PrivateIP string
+	// Managed is true when the instance's SSM agent is online
+	Managed  bool
+	Platform string
+}
+
+// Label names the instance for output: its ID, with its Name tag when set
+func (i Instance) Label() string {
+	if i.Name == "" {
+		return i.ID
+	}
+	return fmt.Sprintf("%s (%s)", i.ID, i.Name)
+}
+
+// Instances returns the running instances sel matches, sorted by name, with
+// their SSM status
+func (a *Agent) Instances(ctx context.Context, sel Selector) ([]Instance, error) {
+	args := []string{"ec2", "describe-instances", "--filters", "Name=instance-state-name,Values=running"}
+	for _, t := range sel.Tags {
+		args = append(args, fmt.Sprintf("Name=tag:%s,Values=%s", t.Key, t.Value))
+	}
+	if sel.Cluster != "" {
+		args = append(args, fmt.Sprintf("Name=tag:kubernetes.io/cluster/%s,Values=owned", sel.Cluster))
+	}
+	if len(sel.InstanceIDs) > 0 {
+		args = append(args, "--instance-ids")
+		args = append(args, sel.InstanceIDs...)
+	}
+	out, err := a.run(ctx, append(args, "--output", "json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instances: %w", err)
+	}
+	instances, err := parseInstances(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("no running instances match %s", sel)
+	}
+
+	managed, err := a.managedInstances(ctx, instances)
+	if err != nil {
+		return nil, err
+	}
+	for i := range instances {
+		if platform, ok := managed[instances[i].ID]; ok {
+			instances[i].Managed = true
+			instances[i].Platform = platform
+		}
+	}
+	return instances, nil
+}
+
+func parseInstances(out string) ([]Instance, error) {
+	var resp struct {
+		Reservations []struct {
+			Instances []struct {
+				InstanceID       string `json:"InstanceId"`
+				PrivateIPAddress string `json:"PrivateIpAddress"`
+				Tags             []struct {
+					Key   string `json:"Key"`
+					Value string `json:"Value"`
+				} `json:"Tags"`
+			} `json:"Instances"`
+		} `json:"Reservations"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse instances: %w", err)
+	}
+	var instances []Instance
+	for _, r := range resp.Reservations {
+		for _, inst := range r.Instances {
+			i := Instance{ID: inst.InstanceID, PrivateIP: inst.PrivateIPAddress}
+			for _, t := range inst.Tags {
+				if t.Key == "Name" {
+					i.Name = t.Value
+				}
+			}
+			instances = append(instances, i)
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Name != instances[j].Name {
+			return instances[i].Name < instances[j].Name
+		}
+		return instances[i].ID < instances[j].ID
+	})
+	return instances, nil
+}
+
+// managedInstances returns the platform of each instance whose SSM agent is
+// online, keyed by instance ID
+func (a *Agent) managedInstances(ctx context.Context, instances []Instance) (map[string]string, error) {
+	managed := map[string]string{}
+	for start := 0; start < len(instances); start += maxFilterValues {
+		end := min(start+maxFilterValues, len(instances))
+		ids := make([]string, 0, end-start)
+		for _, i := range instances[start:end] {
+			ids = append(ids, i.ID)
+		}
+		out, err := a.run(ctx, []string{"ssm", "describe-instance-information",
+			"--filters", "Key=InstanceIds,Values=" + strings.Join(ids, ","), "--output", "json"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SSM status: %w", err)
+		}
+		var resp struct {
+			InstanceInformationList []struct {
+				InstanceID   string `json:"InstanceId"`
+				PingStatus   string `json:"PingStatus"`
+				PlatformName string `json:"PlatformName"`
+			} `json:"InstanceInformationList"`
+		}
+		if err := json.Unmarshal([]byte(out), &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse SSM status: %w", err)
+		}
+		for _, info := range resp.InstanceInformationList {
+			if info.PingStatus == "Online" {
+				managed[info.InstanceID] = info.PlatformName
+			}
+		}
+	}
+	return managed, nil
+}
+
+// SessionArgs returns the aws CLI arguments that open a Session Manager
+// shell on instanceID. The session-manager-plugin must be installed.
+func SessionArgs(instanceID string) []string {
+	return []string{"ssm", "start-session", "--target", instanceID}
+}
+
+// NotManagedError explains why SSM cannot reach an instance
+func NotManagedError(i Instance) error {
+	return fmt.Errorf("%s is not reachable through SSM: its SSM agent is not online (check the agent is running and the instance profile allows ssm:UpdateInstanceInformation, e.g. AmazonSSMManagedInstanceCore)", i.Label())
+}
@@ -0,0 +1,219 @@
+package ssm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/dryrun"
+	"github.com/spf13/viper"
+)
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     Request
+		ok       bool
+	}{
+		{"connect to instance i-0abc12345678def00", Request{Action: Connect, Selector: Selector{InstanceIDs: []string{"i-0abc12345678def00"}}}, true},
+		{"open a shell on i-0abc1234", Request{Action: Connect, Selector: Selector{InstanceIDs: []string{"i-0abc1234"}}}, true},
+		{"ssh into the instance named bastion", Request{Action: Connect, Name: "bastion", Selector: Selector{Tags: []Tag{{"Name", "bastion"}}}}, true},
+		{
+			"run `systemctl status nginx` on all instances tagged role=web",
+			Request{Action: RunCommand, Command: "systemctl status nginx", Selector: Selector{Tags: []Tag{{"role", "web"}}}},
+			true,
+		},
+		{
+			"run df -h on every instance tagged env=prod and tagged role=db",
+			Request{Action: RunCommand, Command: "df -h", Selector: Selector{Tags: []Tag{{"env", "prod"}, {"role", "db"}}}},
+			true,
+		},
+		{
+			`execute "uptime" on the nodes of cluster dev`,
+			Request{Action: RunCommand, Command: "uptime", Selector: Selector{Cluster: "dev"}},
+			true,
+		},
+		{"run uptime on all instances", Request{}, false},
+		{"list ec2 instances tagged role=web", Request{}, false},
+		{"connect to the database", Request{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRequest(tt.question)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRequest(%q) = %+v, %v; want %+v, %v", tt.question, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	if tag, err := ParseTag(" role = web "); err != nil || tag != (Tag{"role", "web"}) {
+		t.Errorf("ParseTag = %+v, %v", tag, err)
+	}
+	for _, bad := range []string{"role", "=web", "role="} {
+		if _, err := ParseTag(bad); err == nil {
+			t.Errorf("ParseTag(%q) should fail", bad)
+		}
+	}
+}
+
+// fakeSSM answers the aws calls Instances and Run make
+type fakeSSM struct {
+	mu    sync.Mutex
+	calls [][]string
+	polls int
+}
+
+func (f *fakeSSM) run(ctx context.Context, args []string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, args)
+	switch strings.Join(args[:2], " ") {
+	case "ec2 describe-instances":
+		return `{"Reservations": [{"Instances": [
+			{"InstanceId": "i-2", "PrivateIpAddress": "10.0.0.2", "Tags": [{"Key": "Name", "Value": "web-b"}]},
+			{"InstanceId": "i-1", "PrivateIpAddress": "10.0.0.1", "Tags": [{"Key": "Name", "Value": "web-a"}]},
+			{"InstanceId": "i-3", "Tags": [{"Key": "Name", "Value": "web-c"}]}
+		]}]}`, nil
+	case "ssm describe-instance-information":
+		return `{"InstanceInformationList": [
+			{"InstanceId": "i-1", "PingStatus": "Online", "PlatformName": "Ubuntu"},
+			{"InstanceId": "i-2", "PingStatus": "Online", "PlatformName": "Ubuntu"},
+			{"InstanceId": "i-3", "PingStatus": "ConnectionLost"}
+		]}`, nil
+	case "ssm send-command":
+		return `{"Command": {"CommandId": "cmd-1"}}`, nil
+	case "ssm list-command-invocations":
+		f.polls++
+		if f.polls == 1 {
+			return `{"CommandInvocations": [{"Status": "Success"}, {"Status": "InProgress"}]}`, nil
+		}
+		return `{"CommandInvocations": [{"Status": "Success"}, {"Status": "Failed"}]}`, nil
+	case "ssm get-command-invocation":
+		if args[5] == "i-1" {
+			return `{"Status": "Success", "ResponseCode": 0, "StandardOutputContent": "up 3 days\n"}`, nil
+		}
+		return `{"Status": "Failed", "ResponseCode": 1, "StandardErrorContent": "nginx: not found\n"}`, nil
+	}
+	return "", errors.New("unexpected call: " + strings.Join(args, " "))
+}
+
+func (f *fakeSSM) call(prefix string) []string {
+	for _, c := range f.calls {
+		if strings.HasPrefix(strings.Join(c, " "), prefix) {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestInstances(t *testing.T) {
+	aws := &fakeSSM{}
+	sel := Selector{Tags: []Tag{{"role", "web"}}, Cluster: "dev"}
+	instances, err := NewAgent(aws.run, false).Instances(context.Background(), sel)
+	if err != nil {
+		t.Fatalf("Instances: %v", err)
+	}
+	want := []Instance{
+		{ID: "i-1", Name: "web-a", PrivateIP: "10.0.0.1", Managed: true, Platform: "Ubuntu"},
+		{ID: "i-2", Name: "web-b", PrivateIP: "10.0.0.2", Managed: true, Platform: "Ubuntu"},
+		{ID: "i-3", Name: "web-c"},
+	}
+	if !slices.Equal(instances, want) {
+		t.Errorf("instances = %+v", instances)
+	}
+
+	describe := aws.call("ec2 describe-instances")
+	for _, filter := range []string{"Name=tag:role,Values=web", "Name=tag:kubernetes.io/cluster/dev,Values=owned", "Name=instance-state-name,Values=running"} {
+		if !slices.Contains(describe, filter) {
+			t.Errorf("describe-instances missing filter %s: %v", filter, describe)
+		}
+	}
+	if info := aws.call("ssm describe-instance-information"); !slices.Contains(info, "Key=InstanceIds,Values=i-1,i-2,i-3") {
+		t.Errorf("describe-instance-information args = %v", info)
+	}
+}
+
+func TestRunCollectsOutput(t *testing.T) {
+	prevDelay := pollDelay
+	pollDelay = 0
+	t.Cleanup(func() { pollDelay = prevDelay })
+
+	aws := &fakeSSM{}
+	agent := NewAgent(aws.run, false)
+	instances, err := agent.Instances(context.Background(), Selector{Tags: []Tag{{"role", "web"}}})
+	if err != nil {
+		t.Fatalf("Instances: %v", err)
+	}
+	result, err := agent.Run(context.Background(), instances, "systemctl status nginx")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if aws.polls != 2 {
+		t.Errorf("polled %d times, want 2", aws.polls)
+	}
+
+	send := aws.call("ssm send-command")
+	if !slices.Contains(send, "AWS-RunShellScript") || !slices.Contains(send, "i-1") || slices.Contains(send, "i-3") {
+		t.Errorf("send-command args = %v", send)
+	}
+	var params map[string][]string
+	if err := json.Unmarshal([]byte(send[slices.Index(send, "--parameters")+1]), &params); err != nil || params["commands"][0] != "systemctl status nginx" {
+		t.Errorf("parameters = %v (%v)", params, err)
+	}
+
+	if result.Succeeded() != 1 || len(result.Skipped) != 1 || result.Skipped[0].ID != "i-3" {
+		t.Errorf("result = %+v", result)
+	}
+	out := result.Format()
+	for _, want := range []string{
+		"=== i-1 (web-a): Success, exit 0 ===\nup 3 days\n",
+		"=== i-2 (web-b): Failed, exit 1 ===\n[stderr]\nnginx: not found\n",
+		`Ran "systemctl status nginx" on 2 instances: 1 succeeded, 1 failed.`,
+		"Skipped (SSM agent not online):\n  - i-3 (web-c)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunDryRunSendsNothing(t *testing.T) {
+	viper.Set("dry_run", true)
+	t.Cleanup(viper.Reset)
+
+	aws := &fakeSSM{}
+	agent := NewAgent(aws.run, false)
+	instances, err := agent.Instances(context.Background(), Selector{Tags: []Tag{{"role", "web"}}})
+	if err != nil {
+		t.Fatalf("Instances: %v", err)
+	}
+	if _, err := agent.Run(context.Background(), instances, "sudo systemctl restart nginx"); !errors.Is(err, dryrun.ErrStopped) {
+		t.Errorf("Run err = %v, want dryrun.ErrStopped", err)
+	}
+	for _, c := range aws.calls {
+		if c[0] == "ssm" && c[1] != "describe-instance-information" {
+			t.Errorf("dry run called aws %s", strings.Join(c, " "))
+		}
+	}
+}
+
+func TestRunWithoutManagedInstances(t *testing.T) {
+	_, err := NewAgent((&fakeSSM{}).run, false).Run(context.Background(), []Instance{{ID: "i-3"}}, "uptime")
+	if err == nil || !strings.Contains(err.Error(), "online SSM agent") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestCommandComment(t *testing.T) {
+	if got := commandComment("uptime  \n -p"); got != "clanker: uptime -p" {
+		t.Errorf("comment = %q", got)
+	}
+	if got := commandComment(strings.Repeat("x", 200)); len(got) != 100 || !strings.HasSuffix(got, "...") {
+		t.Errorf("long comment = %q (%d)", got, len(got))
+	}
+}
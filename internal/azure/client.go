@@ -181,6 +181,12 @@ func (c *Client) execAz(ctx context.Context, args ...string) (string, error) {
 	return "", fmt.Errorf("az command failed: %w, stderr: %s%s", lastErr, lastStderr, azErrorHint(lastStderr))
 }
 
+// ExecCLI runs an az command with the client's subscription, retrying
+// throttled and transient failures
+func (c *Client) ExecCLI(ctx context.Context, args []string) (string, error) {
+	return c.execAz(ctx, args...)
+}
+
 func isRetryableAzError(stderr string) bool {
 	lower := strings.ToLower(stderr)
 	if strings.Contains(lower, "rate") && strings.Contains(lower, "limit") {
@@ -229,6 +235,7 @@ func (c *Client) GetRelevantContext(ctx context.Context, question string) (strin
 		{name: "Resource Groups", args: []string{"group", "list", "--output", "table"}, keys: []string{"resource group", "resource groups", "rg"}},
 		{name: "Azure Resource Graph Inventory", args: []string{"graph", "query", "-q", "Resources | project name, type, location, resourceGroup | limit 200", "--output", "table"}, keys: []string{"resource graph", "inventory", "list all resources"}},
 		{name: "Virtual Machines", args: []string{"vm", "list", "-d", "--output", "table"}, keys: []string{"vm", "vms", "virtual machine", "virtual machines"}},
+		{name: "Virtual Networks", args: []string{"network", "vnet", "list", "--query", "[].{name:name,resourceGroup:resourceGroup,location:location,addressPrefixes:join(', ', addressSpace.addressPrefixes),subnets:length(subnets)}", "--output", "table"}, keys: []string{"vnet", "vnets", "virtual network", "virtual networks", "subnet", "subnets"}},
 		{name: "Network Security Groups", args: []string{"network", "nsg", "list", "--output", "table"}, keys: []string{"nsg", "network security group", "security group", "firewall"}},
 		{name: "Public IPs", args: []string{"network", "public-ip", "list", "--output", "table"}, keys: []string{"public ip", "public ips", "internet", "edge"}},
 		{name: "Private Endpoints", args: []string{"network", "private-endpoint", "list", "--output", "table"}, keys: []string{"private endpoint", "private endpoints", "privatelink"}},
@@ -0,0 +1,139 @@
+package infra
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// Format renders each service the inventory covers as a table, followed by
+// the settings worth a second look
+func (inv *Inventory) Format() string {
+	var sb strings.Builder
+	var findings []string
+	for i, s := range inv.Services {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if err, ok := inv.Errors[s]; ok {
+			fmt.Fprintf(&sb, "%s: %v\n", s.Label(), err)
+			continue
+		}
+		findings = append(findings, inv.formatService(&sb, s)...)
+	}
+	if len(findings) > 0 {
+		sb.WriteString("\nWorth a look:\n")
+		for _, f := range findings {
+			fmt.Fprintf(&sb, "  - %s\n", f)
+		}
+	}
+	return sb.String()
+}
+
+// formatService writes one service's table and returns its findings
+func (inv *Inventory) formatService(sb *strings.Builder, s Service) []string {
+	var count int
+	switch s {
+	case VMs:
+		count = len(inv.VMs)
+	case Storage:
+		count = len(inv.StorageAccounts)
+	case VNets:
+		count = len(inv.VNets)
+	case Functions:
+		count = len(inv.FunctionApps)
+	case Registries:
+		count = len(inv.Registries)
+	}
+	if count == 0 {
+		fmt.Fprintf(sb, "%s: none\n", s.Label())
+		return nil
+	}
+
+	fmt.Fprintf(sb, "%s (%d):\n", s.Label(), count)
+	tw := tabwriter.NewWriter(sb, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+	var findings []string
+	switch s {
+	case VMs:
+		fmt.Fprintln(tw, "  NAME\tRESOURCE GROUP\tLOCATION\tSIZE\tOS\tSTATE\tPUBLIC IP\tPRIVATE IP")
+		for _, v := range inv.VMs {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", v.Name, v.ResourceGroup, v.Location, v.Size,
+				dash(v.OSType), dash(v.PowerState), dash(v.PublicIPs), dash(v.PrivateIPs))
+			if v.PowerState == "stopped" {
+				findings = append(findings, fmt.Sprintf("VM %s is stopped but not deallocated, so its compute is still billed", v.Name))
+			}
+		}
+	case Storage:
+		fmt.Fprintln(tw, "  NAME\tRESOURCE GROUP\tLOCATION\tKIND\tSKU\tHTTPS ONLY\tPUBLIC BLOBS\tMIN TLS")
+		for _, a := range inv.StorageAccounts {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", a.Name, a.ResourceGroup, a.Location, a.Kind, a.SKU,
+				yesNo(a.HTTPSOnly), allowed(a.PublicBlobAccess), dash(a.MinTLS))
+			if a.PublicBlobAccess {
+				findings = append(findings, fmt.Sprintf("storage account %s allows public blob access", a.Name))
+			}
+			if !a.HTTPSOnly {
+				findings = append(findings, fmt.Sprintf("storage account %s accepts plain HTTP", a.Name))
+			}
+			if a.MinTLS == "TLS1_0" || a.MinTLS == "TLS1_1" {
+				findings = append(findings, fmt.Sprintf("storage account %s accepts %s", a.Name, strings.Replace(a.MinTLS, "_", ".", 1)))
+			}
+		}
+	case VNets:
+		fmt.Fprintln(tw, "  NAME\tRESOURCE GROUP\tLOCATION\tADDRESS SPACE\tSUBNETS")
+		for _, v := range inv.VNets {
+			subnets := make([]string, 0, len(v.Subnets))
+			for _, s := range v.Subnets {
+				subnets = append(subnets, fmt.Sprintf("%s (%s)", s.Name, s.AddressPrefix))
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", v.Name, v.ResourceGroup, v.Location,
+				dash(strings.Join(v.AddressPrefixes, ", ")), dash(strings.Join(subnets, ", ")))
+		}
+	case Functions:
+		fmt.Fprintln(tw, "  NAME\tRESOURCE GROUP\tLOCATION\tSTATE\tRUNTIME\tHTTPS ONLY\tHOST")
+		for _, f := range inv.FunctionApps {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\n", f.Name, f.ResourceGroup, f.Location, dash(f.State),
+				dash(f.Runtime), yesNo(f.HTTPSOnly), dash(f.HostName))
+			if !f.HTTPSOnly {
+				findings = append(findings, fmt.Sprintf("function app %s accepts plain HTTP", f.Name))
+			}
+		}
+	case Registries:
+		fmt.Fprintln(tw, "  NAME\tRESOURCE GROUP\tLOCATION\tSKU\tLOGIN SERVER\tADMIN USER")
+		for _, r := range inv.Registries {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", r.Name, r.ResourceGroup, r.Location, r.SKU, r.LoginServer, enabled(r.AdminUserEnabled))
+			if r.AdminUserEnabled {
+				findings = append(findings, fmt.Sprintf("registry %s has the admin user enabled; prefer Entra ID tokens or a managed identity", r.Name))
+			}
+		}
+	}
+	return findings
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func allowed(b bool) string {
+	if b {
+		return "allowed"
+	}
+	return "blocked"
+}
+
+func enabled(b bool) string {
+	if b {
+		return "enabled"
+	}
+	return "disabled"
+}
@@ -0,0 +1,382 @@
+// Package infra answers questions about core Azure infrastructure outside
+// AKS: virtual machines, storage accounts, virtual networks, Function Apps
+// and container registries. It lists them with the settings that matter day
+// to day and turns create, power and resize requests into maker plans to
+// review and apply.
+package infra
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Runner runs an az CLI command and returns its output
+type Runner func(ctx context.Context, args []string) (string, error)
+
+// Agent reads Azure resources and builds plans that change them
+type Agent struct {
+	run   Runner
+	debug bool
+}
+
+// NewAgent creates an Azure infrastructure agent that calls Azure through
+// run
+func NewAgent(run Runner, debug bool) *Agent {
+	return &Agent{run: run, debug: debug}
+}
+
+// Service is one of the core services the agent covers
+type Service string
+
+const (
+	VMs        Service = "vm"
+	Storage    Service = "storage"
+	VNets      Service = "vnet"
+	Functions  Service = "functionapp"
+	Registries Service = "acr"
+)
+
+// Services are the services a listing covers when a question names none,
+// in the order they are shown
+var Services = []Service{VMs, Storage, VNets, Functions, Registries}
+
+// Label is the service's name in listings and plan summaries
+func (s Service) Label() string {
+	switch s {
+	case VMs:
+		return "Virtual machines"
+	case Storage:
+		return "Storage accounts"
+	case VNets:
+		return "Virtual networks"
+	case Functions:
+		return "Function Apps"
+	case Registries:
+		return "Container registries"
+	}
+	return string(s)
+}
+
+// VM is a virtual machine with its size and power state
+type VM struct {
+	Name          string
+	ResourceGroup string
+	Location      string
+	Size          string
+	OSType        string
+	// PowerState is "running", "stopped", "deallocated" and so on
+	PowerState string
+	PublicIPs  string
+	PrivateIPs string
+}
+
+// StorageAccount is a storage account with its redundancy and access
+// settings
+type StorageAccount struct {
+	Name             string
+	ResourceGroup    string
+	Location         string
+	Kind             string
+	SKU              string
+	HTTPSOnly        bool
+	PublicBlobAccess bool
+	MinTLS           string
+}
+
+// VNet is a virtual network with its address space and subnets
+type VNet struct {
+	Name            string
+	ResourceGroup   string
+	Location        string
+	AddressPrefixes []string
+	Subnets         []Subnet
+}
+
+// Subnet is one subnet of a virtual network
+type Subnet struct {
+	Name          string
+	AddressPrefix string
+}
+
+// FunctionApp is a Function App with its state and runtime
+type FunctionApp struct {
+	Name          string
+	ResourceGroup string
+	Location      string
+	State         string
+	Runtime       string
+	HostName      string
+	HTTPSOnly     bool
+}
+
+// Registry is a container registry with its tier and admin user setting
+type Registry struct {
+	Name             string
+	ResourceGroup    string
+	Location         string
+	SKU              string
+	LoginServer      string
+	AdminUserEnabled bool
+}
+
+// Inventory is the resources of each service a listing asked for
+type Inventory struct {
+	Services        []Service
+	VMs             []VM
+	StorageAccounts []StorageAccount
+	VNets           []VNet
+	FunctionApps    []FunctionApp
+	Registries      []Registry
+	// Errors holds the services that could not be listed; the others are
+	// still shown
+	Errors map[Service]error
+}
+
+// runJSON runs an az command with JSON output and decodes it into out
+func (a *Agent) runJSON(ctx context.Context, out any, args ...string) error {
+	raw, err := a.run(ctx, append(args, "--output", "json"))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("failed to parse az %s %s output: %w", args[0], args[1], err)
+	}
+	return nil
+}
+
+// Inventory lists the resources of services, or of every core service when
+// services is empty. A service that cannot be listed is recorded in Errors
+// rather than failing the others.
+func (a *Agent) Inventory(ctx context.Context, services []Service) *Inventory {
+	if len(services) == 0 {
+		services = Services
+	}
+	inv := &Inventory{Services: services, Errors: map[Service]error{}}
+	for _, s := range services {
+		var err error
+		switch s {
+		case VMs:
+			inv.VMs, err = a.VMs(ctx)
+		case Storage:
+			inv.StorageAccounts, err = a.StorageAccounts(ctx)
+		case VNets:
+			inv.VNets, err = a.VNets(ctx)
+		case Functions:
+			inv.FunctionApps, err = a.FunctionApps(ctx)
+		case Registries:
+			inv.Registries, err = a.Registries(ctx)
+		}
+		if err != nil {
+			inv.Errors[s] = err
+		}
+	}
+	if a.debug {
+		fmt.Printf("[azure-infra] listed %d vms, %d storage accounts, %d vnets, %d function apps, %d registries\n",
+			len(inv.VMs), len(inv.StorageAccounts), len(inv.VNets), len(inv.FunctionApps), len(inv.Registries))
+	}
+	return inv
+}
+
+// VMs returns every virtual machine in the subscription with its power
+// state and IP addresses
+func (a *Agent) VMs(ctx context.Context) ([]VM, error) {
+	var resp []struct {
+		Name            string `json:"name"`
+		ResourceGroup   string `json:"resourceGroup"`
+		Location        string `json:"location"`
+		PowerState      string `json:"powerState"`
+		PublicIPs       string `json:"publicIps"`
+		PrivateIPs      string `json:"privateIps"`
+		HardwareProfile struct {
+			VMSize string `json:"vmSize"`
+		} `json:"hardwareProfile"`
+		StorageProfile struct {
+			OSDisk struct {
+				OSType string `json:"osType"`
+			} `json:"osDisk"`
+		} `json:"storageProfile"`
+	}
+	if err := a.runJSON(ctx, &resp, "vm", "list", "-d"); err != nil {
+		return nil, fmt.Errorf("failed to list virtual machines: %w", err)
+	}
+	vms := make([]VM, 0, len(resp))
+	for _, v := range resp {
+		vms = append(vms, VM{
+			Name:          v.Name,
+			ResourceGroup: v.ResourceGroup,
+			Location:      v.Location,
+			Size:          v.HardwareProfile.VMSize,
+			OSType:        v.StorageProfile.OSDisk.OSType,
+			PowerState:    strings.TrimPrefix(strings.ToLower(v.PowerState), "vm "),
+			PublicIPs:     v.PublicIPs,
+			PrivateIPs:    v.PrivateIPs,
+		})
+	}
+	sort.Slice(vms, func(i, j int) bool { return vms[i].Name < vms[j].Name })
+	return vms, nil
+}
+
+// StorageAccounts returns every storage account in the subscription
+func (a *Agent) StorageAccounts(ctx context.Context) ([]StorageAccount, error) {
+	var resp []struct {
+		Name          string `json:"name"`
+		ResourceGroup string `json:"resourceGroup"`
+		Location      string `json:"location"`
+		Kind          string `json:"kind"`
+		SKU           struct {
+			Name string `json:"name"`
+		} `json:"sku"`
+		EnableHTTPSTrafficOnly bool   `json:"enableHttpsTrafficOnly"`
+		AllowBlobPublicAccess  *bool  `json:"allowBlobPublicAccess"`
+		MinimumTLSVersion      string `json:"minimumTlsVersion"`
+	}
+	if err := a.runJSON(ctx, &resp, "storage", "account", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list storage accounts: %w", err)
+	}
+	accounts := make([]StorageAccount, 0, len(resp))
+	for _, s := range resp {
+		accounts = append(accounts, StorageAccount{
+			Name:          s.Name,
+			ResourceGroup: s.ResourceGroup,
+			Location:      s.Location,
+			Kind:          s.Kind,
+			SKU:           s.SKU.Name,
+			HTTPSOnly:     s.EnableHTTPSTrafficOnly,
+			// Accounts created before the setting existed report null and
+			// allow public access
+			PublicBlobAccess: s.AllowBlobPublicAccess == nil || *s.AllowBlobPublicAccess,
+			MinTLS:           s.MinimumTLSVersion,
+		})
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Name < accounts[j].Name })
+	return accounts, nil
+}
+
+// VNets returns every virtual network in the subscription with its subnets
+func (a *Agent) VNets(ctx context.Context) ([]VNet, error) {
+	var resp []struct {
+		Name          string `json:"name"`
+		ResourceGroup string `json:"resourceGroup"`
+		Location      string `json:"location"`
+		AddressSpace  struct {
+			AddressPrefixes []string `json:"addressPrefixes"`
+		} `json:"addressSpace"`
+		Subnets []struct {
+			Name          string `json:"name"`
+			AddressPrefix string `json:"addressPrefix"`
+		} `json:"subnets"`
+	}
+	if err := a.runJSON(ctx, &resp, "network", "vnet", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list virtual networks: %w", err)
+	}
+	vnets := make([]VNet, 0, len(resp))
+	for _, v := range resp {
+		vnet := VNet{
+			Name:            v.Name,
+			ResourceGroup:   v.ResourceGroup,
+			Location:        v.Location,
+			AddressPrefixes: v.AddressSpace.AddressPrefixes,
+		}
+		for _, s := range v.Subnets {
+			vnet.Subnets = append(vnet.Subnets, Subnet{Name: s.Name, AddressPrefix: s.AddressPrefix})
+		}
+		vnets = append(vnets, vnet)
+	}
+	sort.Slice(vnets, func(i, j int) bool { return vnets[i].Name < vnets[j].Name })
+	return vnets, nil
+}
+
+// FunctionApps returns every Function App in the subscription
+func (a *Agent) FunctionApps(ctx context.Context) ([]FunctionApp, error) {
+	var resp []struct {
+		Name            string `json:"name"`
+		ResourceGroup   string `json:"resourceGroup"`
+		Location        string `json:"location"`
+		State           string `json:"state"`
+		DefaultHostName string `json:"defaultHostName"`
+		HTTPSOnly       bool   `json:"httpsOnly"`
+		SiteConfig      struct {
+			LinuxFxVersion string `json:"linuxFxVersion"`
+		} `json:"siteConfig"`
+	}
+	if err := a.runJSON(ctx, &resp, "functionapp", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list function apps: %w", err)
+	}
+	apps := make([]FunctionApp, 0, len(resp))
+	for _, f := range resp {
+		apps = append(apps, FunctionApp{
+			Name:          f.Name,
+			ResourceGroup: f.ResourceGroup,
+			Location:      f.Location,
+			State:         f.State,
+			// linuxFxVersion is "Python|3.11"; Windows apps leave it empty
+			Runtime:   strings.ToLower(strings.ReplaceAll(f.SiteConfig.LinuxFxVersion, "|", " ")),
+			HostName:  f.DefaultHostName,
+			HTTPSOnly: f.HTTPSOnly,
+		})
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+	return apps, nil
+}
+
+// Registries returns every container registry in the subscription
+func (a *Agent) Registries(ctx context.Context) ([]Registry, error) {
+	var resp []struct {
+		Name          string `json:"name"`
+		ResourceGroup string `json:"resourceGroup"`
+		Location      string `json:"location"`
+		LoginServer   string `json:"loginServer"`
+		SKU           struct {
+			Name string `json:"name"`
+		} `json:"sku"`
+		AdminUserEnabled bool `json:"adminUserEnabled"`
+	}
+	if err := a.runJSON(ctx, &resp, "acr", "list"); err != nil {
+		return nil, fmt.Errorf("failed to list container registries: %w", err)
+	}
+	registries := make([]Registry, 0, len(resp))
+	for _, r := range resp {
+		registries = append(registries, Registry{
+			Name:             r.Name,
+			ResourceGroup:    r.ResourceGroup,
+			Location:         r.Location,
+			SKU:              r.SKU.Name,
+			LoginServer:      r.LoginServer,
+			AdminUserEnabled: r.AdminUserEnabled,
+		})
+	}
+	sort.Slice(registries, func(i, j int) bool { return registries[i].Name < registries[j].Name })
+	return registries, nil
+}
+
+// VM returns the virtual machine named name, in resourceGroup when it is
+// set
+func (a *Agent) VM(ctx context.Context, name, resourceGroup string) (VM, error) {
+	vms, err := a.VMs(ctx)
+	if err != nil {
+		return VM{}, err
+	}
+	var matches []VM
+	for _, v := range vms {
+		if strings.EqualFold(v.Name, name) && (resourceGroup == "" || strings.EqualFold(v.ResourceGroup, resourceGroup)) {
+			matches = append(matches, v)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		if resourceGroup != "" {
+			return VM{}, fmt.Errorf("no virtual machine named %s in resource group %s", name, resourceGroup)
+		}
+		return VM{}, fmt.Errorf("no virtual machine named %s", name)
+	case 1:
+		return matches[0], nil
+	}
+	groups := make([]string, 0, len(matches))
+	for _, v := range matches {
+		groups = append(groups, v.ResourceGroup)
+	}
+	return VM{}, fmt.Errorf("%d virtual machines are named %s, in resource groups %s; name the resource group", len(matches), name, strings.Join(groups, ", "))
+}
@@ -0,0 +1,256 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// fakeAz answers az commands from canned JSON keyed by their first three
+// arguments
+type fakeAz struct {
+	responses map[string]string
+	calls     [][]string
+}
+
+func (f *fakeAz) run(ctx context.Context, args []string) (string, error) {
+	f.calls = append(f.calls, args)
+	key := strings.Join(args[:min(3, len(args))], " ")
+	if out, ok := f.responses[key]; ok {
+		if strings.HasPrefix(out, "ERROR:") {
+			return "", errors.New(out)
+		}
+		return out, nil
+	}
+	return "", errors.New("unexpected az " + strings.Join(args, " "))
+}
+
+const testVMs = `[
+	{"name": "web-1", "resourceGroup": "app-rg", "location": "westeurope", "powerState": "VM running",
+	 "publicIps": "20.1.2.3", "privateIps": "10.0.0.4",
+	 "hardwareProfile": {"vmSize": "Standard_B2s"}, "storageProfile": {"osDisk": {"osType": "Linux"}}},
+	{"name": "batch", "resourceGroup": "jobs-rg", "location": "westeurope", "powerState": "VM stopped",
+	 "hardwareProfile": {"vmSize": "Standard_D4s_v5"}, "storageProfile": {"osDisk": {"osType": "Linux"}}}
+]`
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     Request
+	}{
+		{"list my azure vms", Request{Op: List, Services: []Service{VMs}}},
+		{"show azure storage accounts and vnets", Request{Op: List, Services: []Service{Storage, VNets}}},
+		{
+			"create an azure storage account named appdata in resource group app-rg in westeurope",
+			Request{Op: Create, Services: []Service{Storage}, Name: "appdata", ResourceGroup: "app-rg", Location: "westeurope"},
+		},
+		{
+			"create a vnet called core 10.20.0.0/16 with subnet 10.20.1.0/24 in rg net-rg",
+			Request{Op: Create, Services: []Service{VNets}, Name: "core", ResourceGroup: "net-rg", AddressPrefixes: []string{"10.20.0.0/16", "10.20.1.0/24"}},
+		},
+		{
+			"create a python function app named orders-api using storage account ordersdata",
+			Request{Op: Create, Services: []Service{Functions}, Name: "orders-api", Runtime: "python"},
+		},
+		{"create a premium acr named clankerimages", Request{Op: Create, Services: []Service{Registries}, Name: "clankerimages", SKU: "Premium"}},
+		{
+			"spin up an azure ubuntu vm named build-01 size Standard_D2s_v5",
+			Request{Op: Create, Services: []Service{VMs}, Name: "build-01", Size: "Standard_D2s_v5", Image: "ubuntu"},
+		},
+		{"deallocate azure vm batch", Request{Op: Power, Services: []Service{VMs}, Name: "batch", Action: PowerDeallocate}},
+		{"stop the web-1 vm on azure", Request{Op: Power, Services: []Service{VMs}, Name: "web-1", Action: PowerStop}},
+		{"resize azure vm web-1 to Standard_D4s_v5", Request{Op: Resize, Services: []Service{VMs}, Name: "web-1", Size: "Standard_D4s_v5"}},
+	}
+	for _, tt := range tests {
+		if got := ParseRequest(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRequest(%q) =\n  %+v\nwant\n  %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestIsInfraQuestion(t *testing.T) {
+	for _, q := range []string{
+		"list my azure vms",
+		"which azure storage accounts allow public blob access",
+		"create an azure container registry named clankerimages",
+		"show vnets and subnets",
+		"deallocate azure vm batch",
+	} {
+		if !IsInfraQuestion(q) {
+			t.Errorf("IsInfraQuestion(%q) = false", q)
+		}
+	}
+	for _, q := range []string{
+		"list my ec2 instances",
+		"how much do my azure vms cost",
+		"show aks clusters",
+		"delete azure vm batch",
+		"what is in my azure subscription",
+	} {
+		if IsInfraQuestion(q) {
+			t.Errorf("IsInfraQuestion(%q) = true", q)
+		}
+	}
+}
+
+func TestInventoryFormat(t *testing.T) {
+	fake := &fakeAz{responses: map[string]string{
+		"vm list -d": testVMs,
+		"storage account list": `[{"name": "appdata", "resourceGroup": "app-rg", "location": "westeurope", "kind": "StorageV2",
+			"sku": {"name": "Standard_LRS"}, "enableHttpsTrafficOnly": true, "allowBlobPublicAccess": null, "minimumTlsVersion": "TLS1_0"}]`,
+		"network vnet list": `[{"name": "core", "resourceGroup": "net-rg", "location": "westeurope",
+			"addressSpace": {"addressPrefixes": ["10.0.0.0/16"]}, "subnets": [{"name": "default", "addressPrefix": "10.0.0.0/24"}]}]`,
+		"functionapp list --output": "ERROR: AuthorizationFailed",
+		"acr list --output": `[{"name": "clankerimages", "resourceGroup": "app-rg", "location": "westeurope",
+			"loginServer": "clankerimages.azurecr.io", "sku": {"name": "Basic"}, "adminUserEnabled": true}]`,
+	}}
+	out := NewAgent(fake.run, false).Inventory(context.Background(), nil).Format()
+	for _, want := range []string{
+		"Virtual machines (2):",
+		"web-1  app-rg",
+		"20.1.2.3",
+		"core  net-rg",
+		"default (10.0.0.0/24)",
+		"Function Apps: failed to list function apps: ERROR: AuthorizationFailed",
+		"clankerimages.azurecr.io",
+		"VM batch is stopped but not deallocated",
+		"storage account appdata allows public blob access",
+		"storage account appdata accepts TLS1.0",
+		"registry clankerimages has the admin user enabled",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCreatePlans(t *testing.T) {
+	tests := []struct {
+		question string
+		want     [][]string
+	}{
+		{
+			"create an azure storage account named appdata",
+			[][]string{{"storage", "account", "create", "--name", "appdata", "--resource-group", "app-rg", "--location", "westeurope",
+				"--sku", "Standard_LRS", "--kind", "StorageV2", "--https-only", "true", "--min-tls-version", "TLS1_2", "--allow-blob-public-access", "false"}},
+		},
+		{
+			"create a vnet called core with 10.20.0.0/16",
+			[][]string{{"network", "vnet", "create", "--name", "core", "--resource-group", "app-rg", "--location", "westeurope",
+				"--address-prefixes", "10.20.0.0/16", "--subnet-name", "default", "--subnet-prefixes", "10.20.0.0/24"}},
+		},
+		{
+			"create an azure function app named orders-api running node",
+			[][]string{
+				{"storage", "account", "create", "--name", "ordersapist", "--resource-group", "app-rg", "--location", "westeurope",
+					"--sku", "Standard_LRS", "--kind", "StorageV2", "--https-only", "true", "--min-tls-version", "TLS1_2", "--allow-blob-public-access", "false"},
+				{"functionapp", "create", "--name", "orders-api", "--resource-group", "app-rg", "--storage-account", "ordersapist",
+					"--consumption-plan-location", "westeurope", "--runtime", "node", "--functions-version", "4", "--os-type", "Linux", "--https-only", "true"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		plan, err := CreatePlan(ParseRequest(tt.question), "app-rg", "westeurope", false, tt.question, testNow)
+		if err != nil {
+			t.Errorf("CreatePlan(%q): %v", tt.question, err)
+			continue
+		}
+		if plan.Provider != "azure" {
+			t.Errorf("provider = %q", plan.Provider)
+		}
+		var got [][]string
+		for _, c := range plan.Commands {
+			got = append(got, c.Args)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CreatePlan(%q) commands =\n  %v\nwant\n  %v", tt.question, got, tt.want)
+		}
+	}
+
+	for question, want := range map[string]string{
+		"create an azure storage account named App-Data":      "3 to 24 lowercase",
+		"create an azure function app named api":              "name the runtime",
+		"create an azure windows vm named win01":              "admin password",
+		"create a vnet called core 10.20.0.0/16 10.30.0.0/24": "not inside",
+		"create a vnet called core 10.20.0.1/16":              "did you mean 10.20.0.0/16",
+	} {
+		if _, err := CreatePlan(ParseRequest(question), "app-rg", "westeurope", false, question, testNow); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("CreatePlan(%q) error = %v, want %q", question, err, want)
+		}
+	}
+}
+
+func TestPlanCreatesMissingGroup(t *testing.T) {
+	fake := &fakeAz{responses: map[string]string{
+		"group show --name":          "ERROR: (ResourceGroupNotFound) Resource group 'new-rg' could not be found.",
+		"acr check-name --name":      `{"nameAvailable": true}`,
+		"storage account check-name": `{"nameAvailable": false, "message": "The storage account named appdata is already taken."}`,
+	}}
+	agent := NewAgent(fake.run, false)
+	q := "create an azure container registry named clankerimages in resource group new-rg"
+	plan, err := agent.Plan(context.Background(), ParseRequest(q), Defaults{Location: "eastus"}, q, testNow)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if got := plan.Commands[0].Args; !slices.Equal(got, []string{"group", "create", "--name", "new-rg", "--location", "eastus"}) {
+		t.Errorf("first command = %v", got)
+	}
+	if plan.Summary != "Create container registry clankerimages (Basic) in new-rg, eastus" {
+		t.Errorf("summary = %q", plan.Summary)
+	}
+
+	q = "create an azure storage account named appdata in resource group new-rg"
+	if _, err := agent.Plan(context.Background(), ParseRequest(q), Defaults{Location: "eastus"}, q, testNow); err == nil || !strings.Contains(err.Error(), "already taken") {
+		t.Errorf("taken name error = %v", err)
+	}
+	q = "create an azure storage account named appdata"
+	if _, err := agent.Plan(context.Background(), ParseRequest(q), Defaults{}, q, testNow); err == nil || !strings.Contains(err.Error(), "resource group") {
+		t.Errorf("missing group error = %v", err)
+	}
+}
+
+func TestPowerAndResizePlans(t *testing.T) {
+	fake := &fakeAz{responses: map[string]string{
+		"vm list -d":                       testVMs,
+		"vm list-vm-resize-options --name": `[{"name": "Standard_B2s"}, {"name": "Standard_D4s_v5"}]`,
+	}}
+	agent := NewAgent(fake.run, false)
+
+	q := "deallocate azure vm batch"
+	plan, err := agent.Plan(context.Background(), ParseRequest(q), Defaults{}, q, testNow)
+	if err != nil {
+		t.Fatalf("Plan(%q): %v", q, err)
+	}
+	if got := plan.Commands[0].Args; !slices.Equal(got, []string{"vm", "deallocate", "--name", "batch", "--resource-group", "jobs-rg"}) {
+		t.Errorf("deallocate command = %v", got)
+	}
+
+	q = "resize azure vm web-1 to standard_d4s_v5"
+	plan, err = agent.Plan(context.Background(), ParseRequest(q), Defaults{}, q, testNow)
+	if err != nil {
+		t.Fatalf("Plan(%q): %v", q, err)
+	}
+	if got := plan.Commands[0].Args; !slices.Equal(got, []string{"vm", "resize", "--name", "web-1", "--resource-group", "app-rg", "--size", "Standard_D4s_v5"}) {
+		t.Errorf("resize command = %v", got)
+	}
+	if len(plan.Notes) == 0 || !strings.Contains(plan.Notes[0], "restarts") {
+		t.Errorf("resize notes = %v", plan.Notes)
+	}
+
+	for q, want := range map[string]string{
+		"start azure vm web-1":                     "already running",
+		"restart azure vm batch":                   "start it instead",
+		"resize azure vm web-1 to Standard_E8s_v5": "deallocate it",
+		"stop azure vm web-9":                      "no virtual machine named web-9",
+	} {
+		if _, err := agent.Plan(context.Background(), ParseRequest(q), Defaults{}, q, testNow); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Plan(%q) error = %v, want %q", q, err, want)
+		}
+	}
+}
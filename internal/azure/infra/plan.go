@@ -0,0 +1,419 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// Defaults fill in the resource group and location a question leaves out
+type Defaults struct {
+	ResourceGroup string
+	Location      string
+}
+
+// Sizes and images new resources get when a question names none
+const (
+	DefaultVMSize     = "Standard_B2s"
+	DefaultStorageSKU = "Standard_LRS"
+	DefaultACRSKU     = "Basic"
+)
+
+// vmImages maps the images a question can name to their az vm create
+// aliases
+var vmImages = map[string]string{
+	"ubuntu": "Ubuntu2204",
+	"debian": "Debian11",
+}
+
+// FunctionRuntimes are the languages a new Function App can run
+var FunctionRuntimes = []string{"python", "node", "dotnet-isolated", "java", "powershell"}
+
+var (
+	storageNameRe  = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+	registryNameRe = regexp.MustCompile(`^[A-Za-z0-9]{5,50}$`)
+	appNameRe      = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,58}[A-Za-z0-9]$`)
+	vmNameRe       = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,62}$`)
+	vnetNameRe     = regexp.MustCompile(`^[A-Za-z0-9][\w.-]{0,62}[\w]$`)
+)
+
+var errNoService = fmt.Errorf("say what to create: a VM, storage account, VNet, Function App or container registry")
+
+// Plan builds the maker plan for a create, power or resize request. The
+// plan is returned for review; nothing is changed.
+func (a *Agent) Plan(ctx context.Context, req Request, defaults Defaults, question string, now time.Time) (*maker.Plan, error) {
+	switch req.Op {
+	case Create:
+		return a.createPlan(ctx, req, defaults, question, now)
+	case Power, Resize:
+		if req.Name == "" {
+			return nil, fmt.Errorf("name the VM, e.g. \"azure vm web-1\"")
+		}
+		vm, err := a.VM(ctx, req.Name, req.ResourceGroup)
+		if err != nil {
+			return nil, err
+		}
+		if req.Op == Power {
+			return PowerPlan(vm, req.Action, question, now)
+		}
+		sizes, err := a.resizeOptions(ctx, vm)
+		if err != nil {
+			return nil, err
+		}
+		return ResizePlan(vm, req.Size, sizes, question, now)
+	}
+	return nil, fmt.Errorf("nothing to plan for a listing")
+}
+
+func newPlan(question string, now time.Time) *maker.Plan {
+	return &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "azure",
+		Question:  question,
+	}
+}
+
+// createPlan resolves where the new resource goes, checks its name is free
+// and returns the plan that creates it
+func (a *Agent) createPlan(ctx context.Context, req Request, defaults Defaults, question string, now time.Time) (*maker.Plan, error) {
+	if req.Service() == "" {
+		return nil, errNoService
+	}
+	if req.Name == "" {
+		return nil, fmt.Errorf("name the new %s, e.g. \"create an azure %s named app01\"", serviceNoun(req.Service()), serviceNoun(req.Service()))
+	}
+	group := req.ResourceGroup
+	if group == "" {
+		group = defaults.ResourceGroup
+	}
+	if group == "" {
+		return nil, fmt.Errorf("name the resource group, e.g. \"in resource group app-rg\", or set infra.azure.resource_group")
+	}
+
+	var existing struct {
+		Location string `json:"location"`
+	}
+	newGroup := false
+	if err := a.runJSON(ctx, &existing, "group", "show", "--name", group); err != nil {
+		if !strings.Contains(err.Error(), "ResourceGroupNotFound") && !strings.Contains(err.Error(), "could not be found") {
+			return nil, fmt.Errorf("failed to read resource group %s: %w", group, err)
+		}
+		newGroup = true
+	}
+	location := req.Location
+	if location == "" {
+		location = existing.Location
+	}
+	if location == "" {
+		location = defaults.Location
+	}
+	if location == "" {
+		return nil, fmt.Errorf("resource group %s does not exist yet; name a location for it, e.g. \"in westeurope\", or set infra.azure.region", group)
+	}
+
+	if err := a.checkName(ctx, req.Service(), req.Name); err != nil {
+		return nil, err
+	}
+	return CreatePlan(req, group, location, newGroup, question, now)
+}
+
+// checkName asks Azure whether a globally unique storage account or
+// registry name is free
+func (a *Agent) checkName(ctx context.Context, service Service, name string) error {
+	var args []string
+	switch service {
+	case Storage:
+		args = []string{"storage", "account", "check-name", "--name", name}
+	case Registries:
+		args = []string{"acr", "check-name", "--name", name}
+	default:
+		return nil
+	}
+	var resp struct {
+		NameAvailable bool   `json:"nameAvailable"`
+		Message       string `json:"message"`
+	}
+	if err := a.runJSON(ctx, &resp, args...); err != nil {
+		return fmt.Errorf("failed to check the name %s: %w", name, err)
+	}
+	if !resp.NameAvailable {
+		if resp.Message != "" {
+			return fmt.Errorf("%s cannot be used: %s", name, resp.Message)
+		}
+		return fmt.Errorf("%s is already taken", name)
+	}
+	return nil
+}
+
+// CreatePlan returns a plan that creates the resource req describes in
+// group and location, creating the group first when newGroup is set
+func CreatePlan(req Request, group, location string, newGroup bool, question string, now time.Time) (*maker.Plan, error) {
+	plan := newPlan(question, now)
+	if newGroup {
+		plan.Commands = append(plan.Commands, maker.Command{
+			Args:   []string{"group", "create", "--name", group, "--location", location},
+			Reason: "Create the resource group " + group,
+		})
+	}
+	where := []string{"--resource-group", group, "--location", location}
+
+	switch req.Service() {
+	case Storage:
+		if !storageNameRe.MatchString(req.Name) {
+			return nil, fmt.Errorf("storage account names are 3 to 24 lowercase letters and digits; %q is not", req.Name)
+		}
+		sku := req.SKU
+		if sku == "" {
+			sku = DefaultStorageSKU
+		}
+		plan.Summary = fmt.Sprintf("Create storage account %s (%s) in %s, %s", req.Name, sku, group, location)
+		plan.Commands = append(plan.Commands, maker.Command{
+			Args: append([]string{"storage", "account", "create", "--name", req.Name}, append(where,
+				"--sku", sku, "--kind", "StorageV2", "--https-only", "true", "--min-tls-version", "TLS1_2",
+				"--allow-blob-public-access", "false")...),
+			Reason: "Create a general-purpose v2 account that only accepts HTTPS and TLS 1.2",
+		})
+		plan.Notes = append(plan.Notes, "Public blob access is off; grant access with Entra ID roles or SAS tokens")
+
+	case VNets:
+		if !vnetNameRe.MatchString(req.Name) {
+			return nil, fmt.Errorf("%q is not a valid virtual network name", req.Name)
+		}
+		space, subnet, err := vnetPrefixes(req.AddressPrefixes)
+		if err != nil {
+			return nil, err
+		}
+		plan.Summary = fmt.Sprintf("Create virtual network %s (%s) in %s, %s", req.Name, space, group, location)
+		plan.Commands = append(plan.Commands, maker.Command{
+			Args: append([]string{"network", "vnet", "create", "--name", req.Name}, append(where,
+				"--address-prefixes", space, "--subnet-name", "default", "--subnet-prefixes", subnet)...),
+			Reason: fmt.Sprintf("Create the network with a default subnet %s", subnet),
+		})
+		plan.Notes = append(plan.Notes, "Check the address space does not overlap networks you will peer with or reach over VPN")
+
+	case Registries:
+		if !registryNameRe.MatchString(req.Name) {
+			return nil, fmt.Errorf("registry names are 5 to 50 letters and digits; %q is not", req.Name)
+		}
+		sku := req.SKU
+		if sku == "" {
+			sku = DefaultACRSKU
+		}
+		plan.Summary = fmt.Sprintf("Create container registry %s (%s) in %s, %s", req.Name, sku, group, location)
+		plan.Commands = append(plan.Commands, maker.Command{
+			Args:   append([]string{"acr", "create", "--name", req.Name}, append(where, "--sku", sku, "--admin-enabled", "false")...),
+			Reason: "Create the registry with the admin user off",
+		})
+		plan.Notes = append(plan.Notes, fmt.Sprintf("Push with az acr login --name %s; give AKS or apps pull access with the AcrPull role", req.Name))
+
+	case Functions:
+		if !appNameRe.MatchString(req.Name) {
+			return nil, fmt.Errorf("function app names are 2 to 60 letters, digits and hyphens; %q is not", req.Name)
+		}
+		if !slices.Contains(FunctionRuntimes, req.Runtime) {
+			return nil, fmt.Errorf("name the runtime for %s (%s)", req.Name, strings.Join(FunctionRuntimes, ", "))
+		}
+		account := functionStorageName(req.Name)
+		plan.Summary = fmt.Sprintf("Create %s Function App %s on the Consumption plan in %s, %s", req.Runtime, req.Name, group, location)
+		plan.Commands = append(plan.Commands,
+			maker.Command{
+				Args: append([]string{"storage", "account", "create", "--name", account}, append(where,
+					"--sku", DefaultStorageSKU, "--kind", "StorageV2", "--https-only", "true", "--min-tls-version", "TLS1_2",
+					"--allow-blob-public-access", "false")...),
+				Reason: "Create the storage account the Functions host keeps its state in",
+			},
+			maker.Command{
+				Args: []string{"functionapp", "create", "--name", req.Name, "--resource-group", group,
+					"--storage-account", account, "--consumption-plan-location", location,
+					"--runtime", req.Runtime, "--functions-version", "4", "--os-type", "Linux", "--https-only", "true"},
+				Reason: "Create the Function App on a Linux Consumption plan",
+			})
+		plan.Notes = append(plan.Notes, fmt.Sprintf("The storage account name %s is derived from the app name; it must be free across Azure", account))
+
+	case VMs:
+		if !vmNameRe.MatchString(req.Name) {
+			return nil, fmt.Errorf("VM names are up to 64 letters, digits and hyphens; %q is not", req.Name)
+		}
+		if req.Image == "windows" {
+			return nil, fmt.Errorf("Windows VMs need an admin password; plan them with clanker ask --maker")
+		}
+		image := vmImages[req.Image]
+		if image == "" {
+			image = vmImages["ubuntu"]
+		}
+		size := req.Size
+		if size == "" {
+			size = DefaultVMSize
+		}
+		plan.Summary = fmt.Sprintf("Create %s VM %s (%s) in %s, %s", image, req.Name, size, group, location)
+		plan.Commands = append(plan.Commands, maker.Command{
+			Args: append([]string{"vm", "create", "--name", req.Name}, append(where,
+				"--image", image, "--size", size, "--admin-username", "azureuser", "--generate-ssh-keys",
+				"--public-ip-sku", "Standard", "--nsg-rule", "NONE")...),
+			Reason: "Create the VM with SSH key login and no inbound rules",
+		})
+		plan.Notes = append(plan.Notes,
+			"The VM's network security group allows no inbound traffic; reach it through Azure Bastion, or open SSH to your address with an NSG rule",
+			"--generate-ssh-keys uses ~/.ssh/id_rsa.pub, creating it if it does not exist")
+
+	default:
+		return nil, errNoService
+	}
+	if newGroup {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("Resource group %s does not exist and is created in %s", group, location))
+	}
+	return plan, nil
+}
+
+// vnetPrefixes returns the address space and first subnet for a new VNet
+// from the CIDRs a question names. Without a subnet the first /24 of the
+// space is used.
+func vnetPrefixes(cidrs []string) (string, string, error) {
+	space := "10.0.0.0/16"
+	if len(cidrs) > 0 {
+		space = cidrs[0]
+	}
+	spacePrefix, err := netip.ParsePrefix(space)
+	if err != nil || !spacePrefix.Addr().Is4() {
+		return "", "", fmt.Errorf("%s is not an IPv4 CIDR", space)
+	}
+	if spacePrefix.Masked() != spacePrefix {
+		return "", "", fmt.Errorf("%s is not a network address; did you mean %s?", space, spacePrefix.Masked())
+	}
+	if len(cidrs) < 2 {
+		bits := max(spacePrefix.Bits(), 24)
+		return space, netip.PrefixFrom(spacePrefix.Addr(), bits).String(), nil
+	}
+	subnetPrefix, err := netip.ParsePrefix(cidrs[1])
+	if err != nil || subnetPrefix.Masked() != subnetPrefix {
+		return "", "", fmt.Errorf("%s is not a valid subnet CIDR", cidrs[1])
+	}
+	if subnetPrefix.Bits() < spacePrefix.Bits() || !spacePrefix.Contains(subnetPrefix.Addr()) {
+		return "", "", fmt.Errorf("subnet %s is not inside the address space %s", cidrs[1], space)
+	}
+	if subnetPrefix.Bits() > 29 {
+		return "", "", fmt.Errorf("subnet %s is too small; Azure subnets are /29 or larger", cidrs[1])
+	}
+	return space, cidrs[1], nil
+}
+
+// functionStorageName derives the storage account a new Function App keeps
+// its state in from the app's name
+func functionStorageName(app string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(app) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			sb.WriteRune(r)
+		}
+	}
+	name := sb.String()
+	if len(name) > 22 {
+		name = name[:22]
+	}
+	return name + "st"
+}
+
+// PowerPlan returns a plan that starts, stops, deallocates or restarts vm,
+// refusing actions its power state makes pointless
+func PowerPlan(vm VM, action, question string, now time.Time) (*maker.Plan, error) {
+	state := vm.PowerState
+	switch {
+	case action == PowerStart && state == "running":
+		return nil, fmt.Errorf("%s is already running", vm.Name)
+	case action == PowerStop && (state == "stopped" || state == "deallocated"):
+		return nil, fmt.Errorf("%s is already %s", vm.Name, state)
+	case action == PowerDeallocate && state == "deallocated":
+		return nil, fmt.Errorf("%s is already deallocated", vm.Name)
+	case action == PowerRestart && state != "running":
+		return nil, fmt.Errorf("%s is %s, not running; start it instead", vm.Name, dash(state))
+	}
+
+	verbs := map[string]string{PowerStart: "Start", PowerStop: "Stop", PowerDeallocate: "Deallocate", PowerRestart: "Restart"}
+	verb, ok := verbs[action]
+	if !ok {
+		return nil, fmt.Errorf("say whether to start, stop, deallocate or restart %s", vm.Name)
+	}
+	plan := newPlan(question, now)
+	plan.Summary = fmt.Sprintf("%s VM %s in %s", verb, vm.Name, vm.ResourceGroup)
+	plan.Commands = []maker.Command{
+		{Args: []string{"vm", action, "--name", vm.Name, "--resource-group", vm.ResourceGroup},
+			Reason: fmt.Sprintf("%s the VM (currently %s)", verb, dash(state))},
+	}
+	switch action {
+	case PowerStop:
+		plan.Notes = append(plan.Notes, "A stopped VM keeps its hardware and is still billed for compute; deallocate it to stop the charges")
+	case PowerDeallocate:
+		plan.Notes = append(plan.Notes, "Deallocating stops compute charges and releases a dynamic public IP; disks are kept and still billed")
+	}
+	return plan, nil
+}
+
+// resizeOptions returns the sizes vm can move to where it runs now
+func (a *Agent) resizeOptions(ctx context.Context, vm VM) ([]string, error) {
+	var resp []struct {
+		Name string `json:"name"`
+	}
+	if err := a.runJSON(ctx, &resp, "vm", "list-vm-resize-options", "--name", vm.Name, "--resource-group", vm.ResourceGroup); err != nil {
+		return nil, fmt.Errorf("failed to list sizes for %s: %w", vm.Name, err)
+	}
+	sizes := make([]string, 0, len(resp))
+	for _, s := range resp {
+		sizes = append(sizes, s.Name)
+	}
+	return sizes, nil
+}
+
+// ResizePlan returns a plan that moves vm to size, which must be one of the
+// sizes Azure offers it
+func ResizePlan(vm VM, size string, available []string, question string, now time.Time) (*maker.Plan, error) {
+	if size == "" {
+		return nil, fmt.Errorf("name the size to move %s to, e.g. Standard_D4s_v5", vm.Name)
+	}
+	if strings.EqualFold(size, vm.Size) {
+		return nil, fmt.Errorf("%s is already %s", vm.Name, vm.Size)
+	}
+	i := slices.IndexFunc(available, func(s string) bool { return strings.EqualFold(s, size) })
+	if i < 0 {
+		hint := "deallocate it to choose from every size in the region"
+		if vm.PowerState == "deallocated" {
+			hint = "the size is not offered in " + vm.Location
+		}
+		return nil, fmt.Errorf("%s cannot move to %s where it runs now; %s", vm.Name, size, hint)
+	}
+	size = available[i]
+
+	plan := newPlan(question, now)
+	plan.Summary = fmt.Sprintf("Resize VM %s from %s to %s", vm.Name, vm.Size, size)
+	plan.Commands = []maker.Command{
+		{Args: []string{"vm", "resize", "--name", vm.Name, "--resource-group", vm.ResourceGroup, "--size", size},
+			Reason: fmt.Sprintf("Move from %s to %s", vm.Size, size)},
+	}
+	if vm.PowerState == "running" {
+		plan.Notes = append(plan.Notes, "Resizing restarts the VM; expect a few minutes of downtime")
+	}
+	return plan, nil
+}
+
+// serviceNoun is how plans and errors refer to one resource of service
+func serviceNoun(s Service) string {
+	switch s {
+	case VMs:
+		return "VM"
+	case Storage:
+		return "storage account"
+	case VNets:
+		return "VNet"
+	case Functions:
+		return "function app"
+	case Registries:
+		return "registry"
+	}
+	return "resource"
+}
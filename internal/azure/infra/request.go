@@ -0,0 +1,228 @@
+package infra
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/askparse"
+)
+
+// Operation is what a question asks of Azure
+type Operation int
+
+const (
+	// List shows the resources of the services a question names
+	List Operation = iota + 1
+	// Create plans a new resource
+	Create
+	// Power plans starting, stopping, deallocating or restarting a VM
+	Power
+	// Resize plans moving a VM to another size
+	Resize
+)
+
+// Power actions, named as the az vm subcommands that perform them
+const (
+	PowerStart      = "start"
+	PowerStop       = "stop"
+	PowerDeallocate = "deallocate"
+	PowerRestart    = "restart"
+)
+
+// Request is a question translated into an Azure operation
+type Request struct {
+	Op Operation
+	// Services are the services a listing covers; every core service when
+	// empty. Create uses the first.
+	Services      []Service
+	Name          string
+	ResourceGroup string
+	Location      string
+	// Action is the Power action
+	Action string
+	// Size is the VM size for Create and Resize, e.g. Standard_B2s
+	Size string
+	// SKU is the storage redundancy or registry tier for Create
+	SKU string
+	// AddressPrefixes are the CIDRs a question names; for a new VNet the
+	// first is its address space and the second its first subnet
+	AddressPrefixes []string
+	// Runtime is the Function App language for Create
+	Runtime string
+	// Image is the VM image alias for Create
+	Image string
+}
+
+// Service returns the service Create makes
+func (r Request) Service() Service {
+	if len(r.Services) == 0 {
+		return ""
+	}
+	return r.Services[0]
+}
+
+var (
+	azureRe    = regexp.MustCompile(`(?i)\b(?:azure|az|vnets?|acr|function ?apps?|resource groups?)\b`)
+	notInfraRe = regexp.MustCompile(`(?i)\b(?:cost|costs|spend|spending|bill|billing|price|pricing|aks|kubernetes|k8s|devops|pipelines?|logs?|metrics?|alerts?|terraform|bicep|arm template|delete|remove|destroy|why|aws|amazon|ec2|s3|gcp|gce|google)\b`)
+
+	serviceRes = []struct {
+		service Service
+		re      *regexp.Regexp
+	}{
+		{VMs, regexp.MustCompile(`(?i)\b(?:vms?|virtual machines?)\b`)},
+		{Storage, regexp.MustCompile(`(?i)\bstorage(?: accounts?)?\b`)},
+		{VNets, regexp.MustCompile(`(?i)\b(?:vnets?|virtual networks?|subnets?)\b`)},
+		{Functions, regexp.MustCompile(`(?i)\b(?:function ?apps?|azure functions?)\b`)},
+		{Registries, regexp.MustCompile(`(?i)\b(?:acrs?|container registr(?:y|ies)|registr(?:y|ies))\b`)},
+	}
+
+	createRe = regexp.MustCompile(`(?i)\b(?:create|provision|spin up|set up|add|make|new)\b`)
+	powerRes = []struct {
+		action string
+		re     *regexp.Regexp
+	}{
+		{PowerDeallocate, regexp.MustCompile(`(?i)\bdeallocate\b`)},
+		{PowerRestart, regexp.MustCompile(`(?i)\b(?:restart|reboot)\b`)},
+		{PowerStop, regexp.MustCompile(`(?i)\b(?:stop|shut ?down|power off|turn off)\b`)},
+		{PowerStart, regexp.MustCompile(`(?i)\b(?:start|boot|power on|turn on)\b`)},
+	}
+	resizeRe   = regexp.MustCompile(`(?i)\b(?:resize|scale (?:up|down)|change (?:the )?size)\b`)
+	resizeToRe = regexp.MustCompile(`(?i)\b(?:to|into)\s+standard_`)
+
+	sizeRe    = regexp.MustCompile(`(?i)\bStandard_[A-Z][A-Za-z0-9_]*\b`)
+	storeSKU  = regexp.MustCompile(`(?i)\b(?:Standard|Premium)_(?:LRS|GRS|RAGRS|ZRS|GZRS|RAGZRS)\b`)
+	tierRe    = regexp.MustCompile(`(?i)\b(basic|standard|premium)\b`)
+	cidrRe    = regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}/\d{1,2}\b`)
+	runtimeRe = regexp.MustCompile(`(?i)\b(python|node(?:js)?|dotnet-isolated|dotnet|java|powershell)\b`)
+	imageRe   = regexp.MustCompile(`(?i)\b(ubuntu|debian|windows)\b`)
+	groupRe   = regexp.MustCompile(`(?i)\b(?:resource group|rg)\s+["'` + "`" + `]?([\w.()-]+)`)
+	// regionRe matches Azure region names such as eastus2, westeurope,
+	// southeastasia and uksouth
+	regionRe = regexp.MustCompile(`(?i)^(?:(?:north|south|east|west|central)+(?:us|europe|asia|india)\d?|(?:uk|japan|australia|canada|brazil|korea|france|germany|norway|switzerland|uae|southafrica|sweden|poland|italy|israel|qatar|mexico|spain|newzealand|indonesia|malaysia|chile)(?:north|south|east|west|central)+\d?)$`)
+
+	// resourceNames skips words the name patterns can land on that never
+	// name a resource, and region names
+	resourceNames = askparse.Names{
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)\b(?:named|called)\s+["'` + "`" + `]?([\w.-]+)`),
+			regexp.MustCompile(`(?i)\b(?:vm|virtual machine|storage account|vnet|virtual network|function ?app|registry|acr)\s+["'` + "`" + `]?([\w.-]+)`),
+			regexp.MustCompile(`(?i)\b([\w.-]+)\s+(?:vm|virtual machine|storage account|vnet|virtual network|function ?app|registry|acr)\b`),
+		},
+		Skip: askparse.NotNames("new", "every", "azure", "az", "vm", "vms", "vnet", "acr", "registry", "storage",
+			"account", "function", "app", "functionapp", "size", "sku", "start", "stop", "restart",
+			"deallocate", "resize", "create", "list", "show", "basic", "standard", "premium",
+			"ubuntu", "debian", "windows", "linux", "python", "node", "java", "dotnet", "powershell", "are",
+			"container", "virtual", "machine", "network", "running", "stopped"),
+		Trim:   ".",
+		Reject: regionRe.MatchString,
+	}
+)
+
+// ParseRequest reads an Azure operation from question. Questions that ask
+// for no change are listings of the services they name.
+func ParseRequest(question string) Request {
+	req := Request{Op: List}
+	rest := question
+	if m := groupRe.FindStringSubmatch(rest); m != nil {
+		req.ResourceGroup = m[1]
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+	for _, word := range strings.FieldsFunc(rest, func(r rune) bool { return !isWordRune(r) }) {
+		if regionRe.MatchString(word) {
+			req.Location = strings.ToLower(word)
+			break
+		}
+	}
+	req.AddressPrefixes = cidrRe.FindAllString(rest, -1)
+	for _, s := range serviceRes {
+		if s.re.MatchString(rest) {
+			req.Services = append(req.Services, s.service)
+		}
+	}
+	req.Name = resourceNames.Find(rest)
+
+	if m := storeSKU.FindString(rest); m != "" {
+		req.SKU = m
+		rest = strings.Replace(rest, m, " ", 1)
+	}
+	req.Size = sizeRe.FindString(rest)
+	if m := runtimeRe.FindStringSubmatch(rest); m != nil {
+		req.Runtime = normalizeRuntime(m[1])
+	}
+	if m := imageRe.FindStringSubmatch(rest); m != nil {
+		req.Image = strings.ToLower(m[1])
+	}
+
+	isVM := req.Service() == VMs
+	switch {
+	case createRe.MatchString(question) && len(req.Services) > 0:
+		req.Op = Create
+		req.Services = []Service{firstService(rest)}
+		if req.Service() == Registries && req.SKU == "" {
+			if m := tierRe.FindStringSubmatch(rest); m != nil {
+				req.SKU = strings.ToUpper(m[1][:1]) + strings.ToLower(m[1][1:])
+			}
+		}
+	case isVM && resizeRe.MatchString(question):
+		req.Op = Resize
+	case isVM && req.Size != "" && resizeToRe.MatchString(question):
+		req.Op = Resize
+	case isVM:
+		for _, p := range powerRes {
+			if p.re.MatchString(question) {
+				req.Op = Power
+				req.Action = p.action
+				break
+			}
+		}
+	}
+	if req.Op == Power || req.Op == Resize {
+		req.Services = req.Services[:1]
+	}
+	return req
+}
+
+// firstService returns the service question names first, which is the one
+// a create request makes: "a function app using storage account x" makes a
+// Function App
+func firstService(question string) Service {
+	first, at := Service(""), len(question)
+	for _, s := range serviceRes {
+		if loc := s.re.FindStringIndex(question); loc != nil && loc[0] < at {
+			first, at = s.service, loc[0]
+		}
+	}
+	return first
+}
+
+func isWordRune(r rune) bool {
+	return r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// normalizeRuntime maps a language a question names to the
+// functionapp create --runtime value
+func normalizeRuntime(r string) string {
+	switch r = strings.ToLower(r); r {
+	case "nodejs":
+		return "node"
+	case "dotnet":
+		return "dotnet-isolated"
+	}
+	return r
+}
+
+// IsInfraQuestion reports whether question asks to list Azure VMs, storage
+// accounts, VNets, Function Apps or container registries, or to create one
+// or change a VM's power state or size, rather than about AKS, spend,
+// deleting resources or another cloud
+func IsInfraQuestion(question string) bool {
+	if !azureRe.MatchString(question) || notInfraRe.MatchString(question) {
+		return false
+	}
+	for _, s := range serviceRes {
+		if s.re.MatchString(question) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,498 @@
+// Package certs answers questions about TLS certificates across ACM,
+// Cloudflare edge certificates, GCP SSL certificates and cert-manager. It
+// lists every certificate in one report sorted by the days left before it
+// expires, and turns the certificates that will not renew on their own into
+// renewal plans.
+package certs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Runner runs a CLI command and returns its output. The Cloudflare runner
+// takes an HTTP method and an API path instead.
+type Runner func(ctx context.Context, args []string) (string, error)
+
+// Runners are how the agent reaches each source. A nil runner leaves that
+// source out.
+type Runners struct {
+	// AWS runs aws CLI commands
+	AWS Runner
+	// Cloudflare calls the Cloudflare API with a method and a path
+	Cloudflare Runner
+	// GCloud runs gcloud commands in the current project
+	GCloud Runner
+	// Kubectl runs kubectl commands against the current context
+	Kubectl Runner
+}
+
+// Agent reads certificates and builds their renewal plans
+type Agent struct {
+	runners Runners
+	debug   bool
+}
+
+// NewAgent creates a certificate agent that calls each source through its
+// runner
+func NewAgent(runners Runners, debug bool) *Agent {
+	return &Agent{runners: runners, debug: debug}
+}
+
+// Source is a place certificates are issued or kept
+type Source string
+
+const (
+	ACM         Source = "acm"
+	Cloudflare  Source = "cloudflare"
+	GCP         Source = "gcp"
+	CertManager Source = "cert-manager"
+)
+
+// Sources are the sources a report covers when a question names none
+var Sources = []Source{ACM, Cloudflare, GCP, CertManager}
+
+// Label is the source's name in reports
+func (s Source) Label() string {
+	switch s {
+	case ACM:
+		return "ACM"
+	case Cloudflare:
+		return "Cloudflare"
+	case GCP:
+		return "GCP"
+	case CertManager:
+		return "cert-manager"
+	}
+	return string(s)
+}
+
+func (a *Agent) runner(s Source) Runner {
+	switch s {
+	case ACM:
+		return a.runners.AWS
+	case Cloudflare:
+		return a.runners.Cloudflare
+	case GCP:
+		return a.runners.GCloud
+	case CertManager:
+		return a.runners.Kubectl
+	}
+	return nil
+}
+
+// Cert is one certificate and when it expires
+type Cert struct {
+	Source Source
+	// Name is the certificate's name, or its first domain when the source
+	// does not name certificates
+	Name string
+	// ID is the ACM ARN, the Cloudflare certificate ID or the GCP self
+	// link
+	ID      string
+	Domains []string
+	// Kind is how the certificate was issued, such as AMAZON_ISSUED,
+	// IMPORTED, universal, custom, MANAGED or SELF_MANAGED
+	Kind   string
+	Status string
+	Issuer string
+	// Zone and ZoneID are the Cloudflare zone the certificate serves
+	Zone   string
+	ZoneID string
+	// Region is the region of a regional GCP certificate
+	Region string
+	// Namespace and SecretName locate a cert-manager certificate
+	Namespace  string
+	SecretName string
+	NotAfter   time.Time
+	// RenewalTime is when cert-manager plans to renew the certificate
+	RenewalTime time.Time
+	// AutoRenew reports whether the source renews the certificate without
+	// anyone acting
+	AutoRenew bool
+	// InUse reports whether an ACM certificate is attached to a resource
+	InUse bool
+}
+
+// Location is the certificate's name qualified by its namespace or zone
+func (c Cert) Location() string {
+	switch {
+	case c.Namespace != "":
+		return c.Namespace + "/" + c.Name
+	case c.Zone != "" && c.Name != c.Zone:
+		return c.Zone + "/" + c.Name
+	}
+	return c.Name
+}
+
+// DaysLeft is the number of whole days before the certificate expires,
+// negative once it has
+func (c Cert) DaysLeft(now time.Time) int {
+	return int(math.Floor(c.NotAfter.Sub(now).Hours() / 24))
+}
+
+// Report is the certificates of each source a question asked about
+type Report struct {
+	Sources []Source
+	// Certs are sorted by expiry, soonest first, with certificates that
+	// have no expiry yet last
+	Certs []Cert
+	// Errors holds the sources that could not be read; the others are
+	// still reported
+	Errors map[Source]error
+}
+
+// timestamp decodes the ISO 8601 strings and epoch seconds the CLIs use for
+// times
+type timestamp struct {
+	time.Time
+}
+
+func (t *timestamp) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		if s == "" {
+			return nil
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		t.Time = parsed.UTC()
+		return nil
+	}
+	var secs float64
+	if err := json.Unmarshal(b, &secs); err != nil {
+		return err
+	}
+	t.Time = time.Unix(int64(secs), 0).UTC()
+	return nil
+}
+
+// runJSON runs a command that prints JSON and decodes the output into out
+func (a *Agent) runJSON(ctx context.Context, run Runner, out any, args ...string) error {
+	raw, err := run(ctx, args)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("failed to parse %s %s output: %w", args[0], args[1], err)
+	}
+	return nil
+}
+
+// List returns the certificates in sources, or in every source that is
+// configured when sources is empty. A source that cannot be read is
+// recorded in Errors rather than failing the others.
+func (a *Agent) List(ctx context.Context, sources []Source) *Report {
+	if len(sources) == 0 {
+		for _, s := range Sources {
+			if a.runner(s) != nil {
+				sources = append(sources, s)
+			}
+		}
+	}
+	report := &Report{Sources: sources, Errors: map[Source]error{}}
+	for _, s := range sources {
+		if a.runner(s) == nil {
+			report.Errors[s] = fmt.Errorf("%s is not configured", s.Label())
+			continue
+		}
+		var found []Cert
+		var err error
+		switch s {
+		case ACM:
+			found, err = a.acmCerts(ctx)
+		case Cloudflare:
+			found, err = a.cloudflareCerts(ctx)
+		case GCP:
+			found, err = a.gcpCerts(ctx)
+		case CertManager:
+			found, err = a.certManagerCerts(ctx)
+		}
+		if err != nil {
+			report.Errors[s] = err
+			continue
+		}
+		report.Certs = append(report.Certs, found...)
+	}
+	sortCerts(report.Certs)
+	if a.debug {
+		fmt.Printf("[certs] found %d certificates in %d sources\n", len(report.Certs), len(sources)-len(report.Errors))
+	}
+	return report
+}
+
+// acmKeyTypes are every key type ACM issues. list-certificates returns
+// only RSA_2048 certificates unless it is asked for the others.
+const acmKeyTypes = "keyTypes=RSA_1024,RSA_2048,RSA_3072,RSA_4096,EC_prime256v1,EC_secp384r1,EC_secp521r1"
+
+// acmCerts returns the ACM certificates in the current region. Amazon
+// issued and private certificates renew on their own while ACM considers
+// them eligible; imported ones never do.
+func (a *Agent) acmCerts(ctx context.Context) ([]Cert, error) {
+	var resp struct {
+		CertificateSummaryList []struct {
+			CertificateArn                  string
+			DomainName                      string
+			SubjectAlternativeNameSummaries []string
+			Type                            string
+			Status                          string
+			InUse                           bool
+			RenewalEligibility              string
+			NotAfter                        timestamp
+		}
+	}
+	if err := a.runJSON(ctx, a.runners.AWS, &resp, "acm", "list-certificates", "--includes", acmKeyTypes, "--output", "json"); err != nil {
+		return nil, err
+	}
+	out := make([]Cert, 0, len(resp.CertificateSummaryList))
+	for _, c := range resp.CertificateSummaryList {
+		domains := c.SubjectAlternativeNameSummaries
+		if len(domains) == 0 {
+			domains = []string{c.DomainName}
+		}
+		out = append(out, Cert{
+			Source:    ACM,
+			Name:      c.DomainName,
+			ID:        c.CertificateArn,
+			Domains:   domains,
+			Kind:      c.Type,
+			Status:    c.Status,
+			NotAfter:  c.NotAfter.Time,
+			InUse:     c.InUse,
+			AutoRenew: c.Type != "IMPORTED" && c.RenewalEligibility == "ELIGIBLE",
+		})
+	}
+	return out, nil
+}
+
+// cloudflareResult is the envelope every Cloudflare API response comes in
+type cloudflareResult[T any] struct {
+	Result []T `json:"result"`
+}
+
+// cloudflareCerts returns the edge certificates of every zone. Universal
+// and advanced certificate packs are renewed by Cloudflare; uploaded custom
+// certificates are not.
+func (a *Agent) cloudflareCerts(ctx context.Context) ([]Cert, error) {
+	var zones cloudflareResult[struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}]
+	if err := a.runJSON(ctx, a.runners.Cloudflare, &zones, "GET", "/zones?per_page=50"); err != nil {
+		return nil, err
+	}
+	var out []Cert
+	for _, zone := range zones.Result {
+		var packs cloudflareResult[struct {
+			ID           string   `json:"id"`
+			Type         string   `json:"type"`
+			Hosts        []string `json:"hosts"`
+			Status       string   `json:"status"`
+			Authority    string   `json:"certificate_authority"`
+			Certificates []struct {
+				ExpiresOn timestamp `json:"expires_on"`
+				Issuer    string    `json:"issuer"`
+			} `json:"certificates"`
+		}]
+		if err := a.runJSON(ctx, a.runners.Cloudflare, &packs, "GET", fmt.Sprintf("/zones/%s/ssl/certificate_packs?status=all", zone.ID)); err != nil {
+			return nil, fmt.Errorf("zone %s: %w", zone.Name, err)
+		}
+		for _, p := range packs.Result {
+			cert := Cert{
+				Source:    Cloudflare,
+				Name:      firstOr(p.Hosts, zone.Name),
+				ID:        p.ID,
+				Domains:   p.Hosts,
+				Kind:      p.Type,
+				Status:    p.Status,
+				Issuer:    p.Authority,
+				Zone:      zone.Name,
+				ZoneID:    zone.ID,
+				AutoRenew: true,
+			}
+			// A pack holds one certificate per key type; the pack lapses
+			// with the first of them
+			for _, c := range p.Certificates {
+				if cert.NotAfter.IsZero() || (!c.ExpiresOn.IsZero() && c.ExpiresOn.Before(cert.NotAfter)) {
+					cert.NotAfter = c.ExpiresOn.Time
+				}
+			}
+			out = append(out, cert)
+		}
+
+		var custom cloudflareResult[struct {
+			ID        string    `json:"id"`
+			Hosts     []string  `json:"hosts"`
+			Issuer    string    `json:"issuer"`
+			Status    string    `json:"status"`
+			ExpiresOn timestamp `json:"expires_on"`
+		}]
+		if err := a.runJSON(ctx, a.runners.Cloudflare, &custom, "GET", fmt.Sprintf("/zones/%s/custom_certificates", zone.ID)); err != nil {
+			return nil, fmt.Errorf("zone %s: %w", zone.Name, err)
+		}
+		for _, c := range custom.Result {
+			out = append(out, Cert{
+				Source:   Cloudflare,
+				Name:     firstOr(c.Hosts, zone.Name),
+				ID:       c.ID,
+				Domains:  c.Hosts,
+				Kind:     "custom",
+				Status:   c.Status,
+				Issuer:   c.Issuer,
+				Zone:     zone.Name,
+				ZoneID:   zone.ID,
+				NotAfter: c.ExpiresOn.Time,
+			})
+		}
+	}
+	return out, nil
+}
+
+// gcpCerts returns the project's global and regional SSL certificates.
+// Google-managed certificates renew on their own; self-managed ones are
+// replaced by uploading a new certificate.
+func (a *Agent) gcpCerts(ctx context.Context) ([]Cert, error) {
+	var resp []struct {
+		Name                    string    `json:"name"`
+		SelfLink                string    `json:"selfLink"`
+		Type                    string    `json:"type"`
+		Region                  string    `json:"region"`
+		ExpireTime              timestamp `json:"expireTime"`
+		SubjectAlternativeNames []string  `json:"subjectAlternativeNames"`
+		Managed                 struct {
+			Status  string   `json:"status"`
+			Domains []string `json:"domains"`
+		} `json:"managed"`
+	}
+	if err := a.runJSON(ctx, a.runners.GCloud, &resp, "compute", "ssl-certificates", "list", "--format=json"); err != nil {
+		return nil, err
+	}
+	out := make([]Cert, 0, len(resp))
+	for _, c := range resp {
+		domains := c.SubjectAlternativeNames
+		if len(domains) == 0 {
+			domains = c.Managed.Domains
+		}
+		cert := Cert{
+			Source:    GCP,
+			Name:      c.Name,
+			ID:        c.SelfLink,
+			Domains:   domains,
+			Kind:      c.Type,
+			Status:    c.Managed.Status,
+			Region:    lastSegment(c.Region),
+			NotAfter:  c.ExpireTime.Time,
+			AutoRenew: c.Type == "MANAGED",
+		}
+		out = append(out, cert)
+	}
+	return out, nil
+}
+
+// certManagerCerts returns the cert-manager Certificates in every
+// namespace. cert-manager renews a certificate before it expires as long as
+// the certificate is Ready; one that is not is failing to issue.
+func (a *Agent) certManagerCerts(ctx context.Context) ([]Cert, error) {
+	var resp struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				SecretName string   `json:"secretName"`
+				DNSNames   []string `json:"dnsNames"`
+				CommonName string   `json:"commonName"`
+				IssuerRef  struct {
+					Name string `json:"name"`
+					Kind string `json:"kind"`
+				} `json:"issuerRef"`
+			} `json:"spec"`
+			Status struct {
+				NotAfter    timestamp `json:"notAfter"`
+				RenewalTime timestamp `json:"renewalTime"`
+				Conditions  []struct {
+					Type    string `json:"type"`
+					Status  string `json:"status"`
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	err := a.runJSON(ctx, a.runners.Kubectl, &resp, "get", "certificates.cert-manager.io", "-A", "-o", "json")
+	if err != nil {
+		if strings.Contains(err.Error(), "the server doesn't have a resource type") {
+			return nil, fmt.Errorf("cert-manager is not installed in the cluster")
+		}
+		return nil, err
+	}
+	out := make([]Cert, 0, len(resp.Items))
+	for _, c := range resp.Items {
+		domains := c.Spec.DNSNames
+		if len(domains) == 0 && c.Spec.CommonName != "" {
+			domains = []string{c.Spec.CommonName}
+		}
+		cert := Cert{
+			Source:      CertManager,
+			Name:        c.Metadata.Name,
+			Namespace:   c.Metadata.Namespace,
+			SecretName:  c.Spec.SecretName,
+			Domains:     domains,
+			Kind:        c.Spec.IssuerRef.Kind,
+			Issuer:      c.Spec.IssuerRef.Name,
+			Status:      "NotReady",
+			NotAfter:    c.Status.NotAfter.Time,
+			RenewalTime: c.Status.RenewalTime.Time,
+		}
+		for _, cond := range c.Status.Conditions {
+			if cond.Type != "Ready" {
+				continue
+			}
+			if cond.Status == "True" {
+				cert.Status = "Ready"
+				cert.AutoRenew = true
+			} else if cond.Reason != "" {
+				cert.Status = cond.Reason
+			}
+		}
+		out = append(out, cert)
+	}
+	return out, nil
+}
+
+// sortCerts orders certs by expiry, soonest first, with certificates that
+// have no expiry yet last and ties broken by source and name
+func sortCerts(certs []Cert) {
+	sort.SliceStable(certs, func(i, j int) bool {
+		a, b := certs[i], certs[j]
+		if a.NotAfter.IsZero() != b.NotAfter.IsZero() {
+			return b.NotAfter.IsZero()
+		}
+		if !a.NotAfter.Equal(b.NotAfter) {
+			return a.NotAfter.Before(b.NotAfter)
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Location() < b.Location()
+	})
+}
+
+func firstOr(list []string, fallback string) string {
+	if len(list) > 0 && list[0] != "" {
+		return list[0]
+	}
+	return fallback
+}
+
+func lastSegment(link string) string {
+	return link[strings.LastIndex(link, "/")+1:]
+}
@@ -0,0 +1,275 @@
+package certs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// fakeRunner answers commands by the first two args and records every call
+func fakeRunner(calls *[][]string, outputs map[string]string) Runner {
+	return func(ctx context.Context, args []string) (string, error) {
+		*calls = append(*calls, args)
+		if out, ok := outputs[args[0]+" "+args[1]]; ok {
+			return out, nil
+		}
+		return "", errors.New("unexpected call: " + strings.Join(args, " "))
+	}
+}
+
+const (
+	acmOutput = `{"CertificateSummaryList": [
+		{"CertificateArn": "arn:aws:acm:us-east-1:111111111111:certificate/aaa", "DomainName": "shop.example.com",
+		 "SubjectAlternativeNameSummaries": ["shop.example.com", "www.shop.example.com"], "Type": "AMAZON_ISSUED",
+		 "Status": "ISSUED", "InUse": true, "RenewalEligibility": "ELIGIBLE", "NotAfter": "2027-03-01T00:00:00+00:00"},
+		{"CertificateArn": "arn:aws:acm:us-east-1:111111111111:certificate/bbb", "DomainName": "*.legacy.example.com",
+		 "Type": "IMPORTED", "Status": "ISSUED", "InUse": true, "RenewalEligibility": "INELIGIBLE", "NotAfter": 1830297600}
+	]}`
+	zonesOutput = `{"success": true, "result": [{"id": "z1", "name": "example.com"}]}`
+	packsOutput = `{"success": true, "result": [{"id": "p1", "type": "universal", "hosts": ["example.com", "*.example.com"],
+		"status": "active", "certificate_authority": "lets_encrypt",
+		"certificates": [{"expires_on": "2026-12-20T00:00:00Z"}, {"expires_on": "2026-12-01T00:00:00Z"}]}]}`
+	customOutput = `{"success": true, "result": [{"id": "c1", "hosts": ["api.example.com"], "issuer": "DigiCert",
+		"status": "active", "expires_on": "2026-10-25T00:00:00Z"}]}`
+	gcpCertsOutput = `[
+		{"name": "lb-cert", "selfLink": "https://www.googleapis.com/compute/v1/projects/p/global/sslCertificates/lb-cert",
+		 "type": "SELF_MANAGED", "expireTime": "2026-10-20T00:00:00.000-07:00", "subjectAlternativeNames": ["app.example.com"]},
+		{"name": "managed-cert", "type": "MANAGED", "managed": {"status": "PROVISIONING", "domains": ["new.example.com"]}}
+	]`
+	certManagerOutput = `{"items": [
+		{"metadata": {"name": "web-tls", "namespace": "shop"}, "spec": {"secretName": "web-tls", "dnsNames": ["web.example.com"],
+		 "issuerRef": {"name": "letsencrypt", "kind": "ClusterIssuer"}},
+		 "status": {"notAfter": "2026-10-18T00:00:00Z", "conditions": [{"type": "Ready", "status": "False", "reason": "Failed"}]}}
+	]}`
+	proxiesOutput = `[{"name": "web-proxy", "sslCertificates": [
+		"https://www.googleapis.com/compute/v1/projects/p/global/sslCertificates/lb-cert",
+		"https://www.googleapis.com/compute/v1/projects/p/global/sslCertificates/other"]}]`
+)
+
+func testAgent(calls *[][]string) *Agent {
+	return NewAgent(Runners{
+		AWS: fakeRunner(calls, map[string]string{"acm list-certificates": acmOutput}),
+		Cloudflare: fakeRunner(calls, map[string]string{
+			"GET /zones?per_page=50":                         zonesOutput,
+			"GET /zones/z1/ssl/certificate_packs?status=all": packsOutput,
+			"GET /zones/z1/custom_certificates":              customOutput,
+		}),
+		GCloud: fakeRunner(calls, map[string]string{
+			"compute ssl-certificates":     gcpCertsOutput,
+			"compute target-https-proxies": proxiesOutput,
+		}),
+		Kubectl: fakeRunner(calls, map[string]string{"get certificates.cert-manager.io": certManagerOutput}),
+	}, false)
+}
+
+func TestList(t *testing.T) {
+	var calls [][]string
+	report := testAgent(&calls).List(context.Background(), nil)
+	if len(report.Errors) != 0 {
+		t.Fatalf("List() errors = %v", report.Errors)
+	}
+	var got []string
+	for _, c := range report.Certs {
+		got = append(got, string(c.Source)+":"+c.Location())
+	}
+	want := []string{
+		"cert-manager:shop/web-tls",
+		"gcp:lb-cert",
+		"cloudflare:example.com/api.example.com",
+		"cloudflare:example.com",
+		"acm:shop.example.com",
+		"acm:*.legacy.example.com",
+		"gcp:managed-cert",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("certs = %v, want %v", got, want)
+	}
+
+	byName := map[string]Cert{}
+	for _, c := range report.Certs {
+		byName[c.Location()] = c
+	}
+	if c := byName["example.com"]; !c.NotAfter.Equal(time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)) || !c.AutoRenew {
+		t.Errorf("universal pack = %+v, want the earliest certificate's expiry and auto renewal", c)
+	}
+	if c := byName["*.legacy.example.com"]; c.AutoRenew || !c.NotAfter.Equal(time.Unix(1830297600, 0).UTC()) {
+		t.Errorf("imported ACM cert = %+v", c)
+	}
+	if c := byName["shop.example.com"]; !c.AutoRenew || len(c.Domains) != 2 {
+		t.Errorf("amazon issued ACM cert = %+v", c)
+	}
+	if c := byName["shop/web-tls"]; c.AutoRenew || c.Status != "Failed" {
+		t.Errorf("failing cert-manager cert = %+v", c)
+	}
+	if got := byName["lb-cert"].DaysLeft(testNow); got != 4 {
+		t.Errorf("lb-cert DaysLeft = %d, want 4", got)
+	}
+
+	for _, call := range calls {
+		if call[0] == "acm" && !strings.Contains(strings.Join(call, " "), "EC_prime256v1") {
+			t.Errorf("acm call %v does not ask for every key type", call)
+		}
+	}
+}
+
+func TestListRecordsSourceErrors(t *testing.T) {
+	agent := NewAgent(Runners{
+		Kubectl: func(ctx context.Context, args []string) (string, error) {
+			return "", errors.New(`error: the server doesn't have a resource type "certificates"`)
+		},
+	}, false)
+	report := agent.List(context.Background(), []Source{ACM, CertManager})
+	if len(report.Certs) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	if err := report.Errors[ACM]; err == nil || !strings.Contains(err.Error(), "not configured") {
+		t.Errorf("ACM error = %v", err)
+	}
+	if err := report.Errors[CertManager]; err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("cert-manager error = %v", err)
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     Request
+	}{
+		{"which certs expire soon", Request{Within: 30, Expiring: true}},
+		{"list all certificates", Request{Within: 30}},
+		{"acm certificates expiring in the next 14 days", Request{Sources: []Source{ACM}, Within: 14, Expiring: true}},
+		{"cloudflare and cert-manager certs expiring within 2 weeks", Request{Sources: []Source{Cloudflare, CertManager}, Within: 14, Expiring: true}},
+		{"gcp ssl certificates that expire in 3 months", Request{Sources: []Source{GCP}, Within: 90, Expiring: true}},
+	}
+	for _, tt := range tests {
+		if got := ParseRequest(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestIsCertsQuestion(t *testing.T) {
+	yes := []string{
+		"which certs expire soon",
+		"list acm certificates",
+		"when does the tls cert for shop.example.com expire",
+		"ssl expiring in the next 10 days",
+		"show cert-manager certificates",
+	}
+	no := []string{
+		"check kubeadm certs expiration",
+		"rotate the kubelet client certificate",
+		"which load balancers use tls 1.0",
+		"list ec2 instances",
+	}
+	for _, q := range yes {
+		if !IsCertsQuestion(q) {
+			t.Errorf("IsCertsQuestion(%q) = false, want true", q)
+		}
+	}
+	for _, q := range no {
+		if IsCertsQuestion(q) {
+			t.Errorf("IsCertsQuestion(%q) = true, want false", q)
+		}
+	}
+}
+
+func TestRenewals(t *testing.T) {
+	var calls [][]string
+	agent := testAgent(&calls)
+	report := agent.List(context.Background(), nil)
+	renewals := agent.Renewals(context.Background(), report, 30, "which certs expire soon", testNow)
+
+	if len(renewals.Plans) != 1 {
+		t.Fatalf("plans = %+v, want only the GCP plan", renewals.Plans)
+	}
+	plan := renewals.Plans[0]
+	if plan.Provider != "gcp" || plan.Summary != "Replace 1 GCP SSL certificate" {
+		t.Errorf("plan = %+v", plan)
+	}
+	var got []string
+	for _, cmd := range plan.Commands {
+		got = append(got, strings.Join(cmd.Args, " "))
+	}
+	want := []string{
+		"compute ssl-certificates create lb-cert-20261015 --certificate=lb-cert.crt --private-key=lb-cert.key --global",
+		"compute target-https-proxies update web-proxy --ssl-certificates=lb-cert-20261015,other --global",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+
+	wantSteps := []string{"kubectl describe certificate web-tls -n shop", "cmctl renew web-tls -n shop"}
+	if !reflect.DeepEqual(renewals.Steps, wantSteps) {
+		t.Errorf("steps = %v, want %v", renewals.Steps, wantSteps)
+	}
+	notes := strings.Join(renewals.Notes, "\n")
+	for _, want := range []string{"Custom certificate api.example.com in zone example.com expires in 9 days", "shop/web-tls (Failed)"} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("notes missing %q:\n%s", want, notes)
+		}
+	}
+	// The imported ACM certificate is months away and the universal pack
+	// renews on its own
+	if strings.Contains(notes, "legacy") || strings.Contains(notes, "universal") {
+		t.Errorf("notes mention certificates that are not due:\n%s", notes)
+	}
+}
+
+func TestBuildRenewalsACM(t *testing.T) {
+	due := []Cert{
+		{Source: ACM, Name: "*.legacy.example.com", ID: "arn:imported", Kind: "IMPORTED", NotAfter: testNow.Add(5 * 24 * time.Hour)},
+		{Source: ACM, Name: "internal.example.com", ID: "arn:private", Kind: "PRIVATE", NotAfter: testNow.Add(20 * 24 * time.Hour)},
+		{Source: ACM, Name: "old.example.com", ID: "arn:unused", Kind: "AMAZON_ISSUED", NotAfter: testNow.Add(-48 * time.Hour)},
+	}
+	renewals := BuildRenewals(due, nil, "renew acm certs", testNow)
+	if len(renewals.Plans) != 1 || renewals.Plans[0].Provider != "aws" || renewals.Plans[0].Summary != "Renew 2 ACM certificates" {
+		t.Fatalf("plans = %+v", renewals.Plans)
+	}
+	var got []string
+	for _, cmd := range renewals.Plans[0].Commands {
+		got = append(got, strings.Join(cmd.Args, " "))
+	}
+	want := []string{
+		"acm import-certificate --certificate-arn arn:imported --certificate fileb://wildcard.legacy.example.com.crt --private-key fileb://wildcard.legacy.example.com.key --certificate-chain fileb://wildcard.legacy.example.com-chain.crt",
+		"acm renew-certificate --certificate-arn arn:private",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+	if len(renewals.Notes) != 1 || !strings.Contains(renewals.Notes[0], "old.example.com expired 2 days ago and is not attached") {
+		t.Errorf("notes = %v", renewals.Notes)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	var calls [][]string
+	report := testAgent(&calls).List(context.Background(), nil)
+	report.Errors[ACM] = errors.New("AccessDenied")
+
+	out := report.Format(testNow, 30, true)
+	for _, want := range []string{
+		"Certificates expiring within 30 days (3):",
+		"shop/web-tls",
+		"failing",
+		"3 other certificates expire later.",
+		"ACM: not read (AccessDenied)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Format() missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "managed-cert") {
+		t.Errorf("expiring report lists a certificate that is not issued yet:\n%s", out)
+	}
+
+	full := report.Format(testNow, 30, false)
+	if !strings.Contains(full, "Certificates by expiry (6):") || !strings.Contains(full, "Not issued yet:\n  GCP managed-cert (PROVISIONING)") {
+		t.Errorf("full report:\n%s", full)
+	}
+}
@@ -0,0 +1,119 @@
+package certs
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// maxDomains is how many domains a report row shows before summarizing the
+// rest
+const maxDomains = 3
+
+// Format renders the report as one table sorted by days left. With
+// expiringOnly set it shows only the certificates expiring within days and
+// counts the rest.
+func (r *Report) Format(now time.Time, within int, expiringOnly bool) string {
+	var sb strings.Builder
+	var shown, pending []Cert
+	later := 0
+	for _, c := range r.Certs {
+		switch {
+		case c.NotAfter.IsZero():
+			pending = append(pending, c)
+		case expiringOnly && c.DaysLeft(now) > within:
+			later++
+		default:
+			shown = append(shown, c)
+		}
+	}
+
+	switch {
+	case len(r.Sources) == 0:
+		sb.WriteString("No certificate sources are configured.\n")
+	case expiringOnly && len(shown) == 0:
+		fmt.Fprintf(&sb, "No certificates expire within %d days.\n", within)
+	case expiringOnly:
+		fmt.Fprintf(&sb, "Certificates expiring within %d days (%d):\n", within, len(shown))
+	case len(shown) == 0:
+		sb.WriteString("No certificates found.\n")
+	default:
+		fmt.Fprintf(&sb, "Certificates by expiry (%d):\n", len(shown))
+	}
+	if len(shown) > 0 {
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  DAYS\tEXPIRES\tSOURCE\tCERTIFICATE\tDOMAINS\tRENEWAL\tSTATUS")
+		for _, c := range shown {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\n", daysLabel(c.DaysLeft(now)), c.NotAfter.UTC().Format("2006-01-02"),
+				c.Source.Label(), c.Location(), domains(c.Domains), renewal(c), orDash(c.Status))
+		}
+		tw.Flush()
+	}
+	if later > 0 {
+		fmt.Fprintf(&sb, "%s expire later.\n", countOf(later, "other certificate"))
+	}
+	if len(pending) > 0 && !expiringOnly {
+		sb.WriteString("\nNot issued yet:\n")
+		for _, c := range pending {
+			fmt.Fprintf(&sb, "  %s %s (%s)\n", c.Source.Label(), c.Location(), orDash(c.Status))
+		}
+	}
+	for _, s := range r.Sources {
+		if err, failed := r.Errors[s]; failed {
+			fmt.Fprintf(&sb, "%s: not read (%v)\n", s.Label(), err)
+		}
+	}
+	return sb.String()
+}
+
+// Format renders the renewal steps and notes; plans are printed by the
+// caller
+func (r *Renewals) Format() string {
+	var sb strings.Builder
+	if len(r.Steps) > 0 {
+		sb.WriteString("Run:\n")
+		for _, step := range r.Steps {
+			fmt.Fprintf(&sb, "  %s\n", step)
+		}
+	}
+	if len(r.Notes) > 0 {
+		sb.WriteString("Notes:\n")
+	}
+	for _, note := range r.Notes {
+		fmt.Fprintf(&sb, "  - %s\n", note)
+	}
+	return sb.String()
+}
+
+func daysLabel(days int) string {
+	if days < 0 {
+		return "expired"
+	}
+	return fmt.Sprintf("%d", days)
+}
+
+func domains(list []string) string {
+	if len(list) <= maxDomains {
+		return orDash(strings.Join(list, ","))
+	}
+	return fmt.Sprintf("%s +%d more", strings.Join(list[:maxDomains], ","), len(list)-maxDomains)
+}
+
+// renewal says who renews the certificate
+func renewal(c Cert) string {
+	if c.AutoRenew {
+		return "auto"
+	}
+	if c.Source == CertManager {
+		return "failing"
+	}
+	return "manual"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
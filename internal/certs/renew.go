@@ -0,0 +1,236 @@
+package certs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// stuckWithin is how close to expiry a certificate that renews on its own
+// can get before the report treats its renewal as stuck
+const stuckWithin = 7
+
+// Renewals are the plans and steps that renew the certificates due within
+// a report's window. ACM and GCP renewals are maker plans, at most one per
+// provider; cert-manager renewals are kubectl and cmctl steps, and anything
+// that needs a new certificate from outside the cloud is a note.
+type Renewals struct {
+	Plans []*maker.Plan
+	Steps []string
+	Notes []string
+}
+
+// Proxy is a GCP target HTTPS proxy and the names of the SSL certificates
+// it serves
+type Proxy struct {
+	Name   string
+	Region string
+	Certs  []string
+}
+
+// Due returns the certificates that need someone to act within days: those
+// that will not renew on their own, and those that should have renewed by
+// now but have not
+func Due(certs []Cert, within int, now time.Time) []Cert {
+	var due []Cert
+	for _, c := range certs {
+		if c.NotAfter.IsZero() {
+			continue
+		}
+		days := c.DaysLeft(now)
+		if (!c.AutoRenew && days <= within) || days <= stuckWithin {
+			due = append(due, c)
+		}
+	}
+	return due
+}
+
+// Renewals builds the renewals of the certificates in report that are due
+// within days, looking up the HTTPS proxies that serve self-managed GCP
+// certificates so the plan can switch them over
+func (a *Agent) Renewals(ctx context.Context, report *Report, within int, question string, now time.Time) *Renewals {
+	due := Due(report.Certs, within, now)
+	var proxies []Proxy
+	var proxyErr error
+	for _, c := range due {
+		if c.Source == GCP && !c.AutoRenew {
+			proxies, proxyErr = a.httpsProxies(ctx)
+			break
+		}
+	}
+	renewals := BuildRenewals(due, proxies, question, now)
+	if proxyErr != nil {
+		renewals.Notes = append(renewals.Notes, fmt.Sprintf("Could not list GCP HTTPS proxies (%v); switch the proxies that serve the replaced certificates by hand.", proxyErr))
+	}
+	return renewals
+}
+
+// httpsProxies returns the project's target HTTPS proxies
+func (a *Agent) httpsProxies(ctx context.Context) ([]Proxy, error) {
+	var resp []struct {
+		Name            string   `json:"name"`
+		Region          string   `json:"region"`
+		SSLCertificates []string `json:"sslCertificates"`
+	}
+	if err := a.runJSON(ctx, a.runners.GCloud, &resp, "compute", "target-https-proxies", "list", "--format=json"); err != nil {
+		return nil, err
+	}
+	out := make([]Proxy, 0, len(resp))
+	for _, p := range resp {
+		proxy := Proxy{Name: p.Name, Region: lastSegment(p.Region)}
+		for _, link := range p.SSLCertificates {
+			proxy.Certs = append(proxy.Certs, lastSegment(link))
+		}
+		out = append(out, proxy)
+	}
+	return out, nil
+}
+
+// BuildRenewals builds the renewals of due, switching the proxies that
+// serve a replaced GCP certificate to its successor
+func BuildRenewals(due []Cert, proxies []Proxy, question string, now time.Time) *Renewals {
+	renewals := &Renewals{}
+	var awsCmds, gcpCmds []maker.Command
+	var awsNotes, gcpNotes []string
+	for _, c := range due {
+		days := c.DaysLeft(now)
+		if c.AutoRenew {
+			renewals.Notes = append(renewals.Notes, fmt.Sprintf("%s %s renews automatically but %s; check why %s has not renewed it.",
+				c.Source.Label(), c.Location(), expiresIn(days), c.Source.Label()))
+			continue
+		}
+		switch c.Source {
+		case ACM:
+			switch c.Kind {
+			case "IMPORTED":
+				base := fileBase(c.Name)
+				awsCmds = append(awsCmds, maker.Command{
+					Args: []string{"acm", "import-certificate", "--certificate-arn", c.ID,
+						"--certificate", "fileb://" + base + ".crt",
+						"--private-key", "fileb://" + base + ".key",
+						"--certificate-chain", "fileb://" + base + "-chain.crt"},
+					Reason: fmt.Sprintf("Reimport the renewed certificate for %s under the same ARN, so everything using it serves the new one", c.Name),
+				})
+				awsNotes = append(awsNotes, fmt.Sprintf("ACM does not renew imported certificates. Save the renewed certificate for %s, its private key and its chain as %s.crt, %s.key and %s-chain.crt before applying.", c.Name, base, base, base))
+			case "PRIVATE":
+				awsCmds = append(awsCmds, maker.Command{
+					Args:   []string{"acm", "renew-certificate", "--certificate-arn", c.ID},
+					Reason: fmt.Sprintf("Renew the private certificate for %s from its private CA", c.Name),
+				})
+			default:
+				if !c.InUse {
+					renewals.Notes = append(renewals.Notes, fmt.Sprintf("ACM only renews certificates that are in use. %s %s and is not attached to anything; attach it or delete it.", c.Name, expiresIn(days)))
+				} else {
+					renewals.Notes = append(renewals.Notes, fmt.Sprintf("ACM cannot renew %s (%s), which %s; check its validation records with: aws acm describe-certificate --certificate-arn %s", c.Name, c.Status, expiresIn(days), c.ID))
+				}
+			}
+		case GCP:
+			base := fileBase(c.Name)
+			next := fmt.Sprintf("%s-%s", c.Name, now.UTC().Format("20060102"))
+			args := []string{"compute", "ssl-certificates", "create", next, "--certificate=" + base + ".crt", "--private-key=" + base + ".key"}
+			gcpCmds = append(gcpCmds, maker.Command{
+				Args:   append(args, gcpScope(c.Region)),
+				Reason: fmt.Sprintf("Upload the renewed certificate for %s as %s", c.Name, next),
+			})
+			switched := 0
+			for _, p := range proxies {
+				if p.Region != c.Region || !containsString(p.Certs, c.Name) {
+					continue
+				}
+				certs := make([]string, len(p.Certs))
+				for i, name := range p.Certs {
+					certs[i] = name
+					if name == c.Name {
+						certs[i] = next
+					}
+				}
+				gcpCmds = append(gcpCmds, maker.Command{
+					Args:   []string{"compute", "target-https-proxies", "update", p.Name, "--ssl-certificates=" + strings.Join(certs, ","), gcpScope(c.Region)},
+					Reason: fmt.Sprintf("Serve %s from HTTPS proxy %s in place of %s", next, p.Name, c.Name),
+				})
+				switched++
+			}
+			gcpNotes = append(gcpNotes, fmt.Sprintf("Save the renewed certificate for %s and its private key as %s.crt and %s.key before applying.", c.Name, base, base))
+			if switched == 0 {
+				gcpNotes = append(gcpNotes, fmt.Sprintf("No HTTPS proxy serves %s; point whatever uses it at %s.", c.Name, next))
+			}
+			gcpNotes = append(gcpNotes, fmt.Sprintf("Once %s serves traffic, delete the old certificate with: gcloud compute ssl-certificates delete %s %s", next, c.Name, gcpScope(c.Region)))
+		case Cloudflare:
+			renewals.Notes = append(renewals.Notes, fmt.Sprintf("Cloudflare does not renew uploaded certificates. Custom certificate %s in zone %s %s; upload its replacement in the dashboard or with PATCH /zones/%s/custom_certificates/%s.",
+				c.Name, c.Zone, expiresIn(days), c.ZoneID, c.ID))
+		case CertManager:
+			renewals.Steps = append(renewals.Steps,
+				fmt.Sprintf("kubectl describe certificate %s -n %s", c.Name, c.Namespace),
+				fmt.Sprintf("cmctl renew %s -n %s", c.Name, c.Namespace))
+			renewals.Notes = append(renewals.Notes, fmt.Sprintf("cert-manager is not renewing %s (%s), which %s. Fix what kubectl describe reports, then trigger a renewal with cmctl.", c.Location(), c.Status, expiresIn(days)))
+		}
+	}
+	if len(awsCmds) > 0 {
+		renewals.Plans = append(renewals.Plans, renewalPlan("aws", fmt.Sprintf("Renew %s", countOf(len(awsCmds), "ACM certificate")), question, awsCmds, awsNotes, now))
+	}
+	if len(gcpCmds) > 0 {
+		n := 0
+		for _, cmd := range gcpCmds {
+			if cmd.Args[1] == "ssl-certificates" {
+				n++
+			}
+		}
+		renewals.Plans = append(renewals.Plans, renewalPlan("gcp", fmt.Sprintf("Replace %s", countOf(n, "GCP SSL certificate")), question, gcpCmds, gcpNotes, now))
+	}
+	return renewals
+}
+
+func renewalPlan(provider, summary, question string, cmds []maker.Command, notes []string, now time.Time) *maker.Plan {
+	return &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  provider,
+		Question:  question,
+		Summary:   summary,
+		Commands:  cmds,
+		Notes:     notes,
+	}
+}
+
+// gcpScope is the flag that places a GCP certificate or proxy in region, or
+// globally when it has none
+func gcpScope(region string) string {
+	if region == "" {
+		return "--global"
+	}
+	return "--region=" + region
+}
+
+// fileBase is the file name a certificate's renewed files are read from
+func fileBase(name string) string {
+	return strings.ReplaceAll(name, "*", "wildcard")
+}
+
+func expiresIn(days int) string {
+	switch {
+	case days < 0:
+		return fmt.Sprintf("expired %s ago", countOf(-days, "day"))
+	case days == 0:
+		return "expires today"
+	}
+	return fmt.Sprintf("expires in %s", countOf(days, "day"))
+}
+
+func countOf(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func containsString(list []string, s string) bool {
+	for _, have := range list {
+		if have == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package certs
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultWindow is how many days ahead "expiring soon" looks
+const DefaultWindow = 30
+
+// Request is a question translated into a certificate report
+type Request struct {
+	// Sources are the sources the question names; empty means every
+	// configured source
+	Sources []Source
+	// Within is how many days ahead counts as expiring
+	Within int
+	// Expiring limits the report to certificates expiring within Within
+	// days; otherwise every certificate is listed
+	Expiring bool
+}
+
+var (
+	certRe    = regexp.MustCompile(`(?i)\b(?:certs?|certificates?|acm|cert-?manager)\b`)
+	tlsRe     = regexp.MustCompile(`(?i)\b(?:tls|ssl|https)\b`)
+	notCertRe = regexp.MustCompile(`(?i)\b(?:kubeadm|etcd|kubelet|apiserver|api server|kubeconfig|client cert(?:ificate)?s?|ssh|mtls|ca bundle|signing|code signing|cost|costs|spend|bill|billing)\b`)
+	expiryRe  = regexp.MustCompile(`(?i)\b(?:expir\w*|renew\w*|lapse[sd]?|valid until|days left|out of date)\b`)
+	listRe    = regexp.MustCompile(`(?i)\b(?:list|show|which|what|how many|any|inventory|all|report|overview|status)\b`)
+	soonRe    = regexp.MustCompile(`(?i)\b(?:soon|upcoming|about to)\b`)
+	windowRe  = regexp.MustCompile(`(?i)\b(?:next|within|in|under|less than)\s+(?:the\s+next\s+)?(\d+)\s+(days?|weeks?|months?)\b`)
+
+	acmRe         = regexp.MustCompile(`(?i)\b(?:acm|aws)\b|\bamazon certificate`)
+	cloudflareRe  = regexp.MustCompile(`(?i)\bcloudflare\b|\bedge cert`)
+	gcpRe         = regexp.MustCompile(`(?i)\b(?:gcp|google|gcloud)\b`)
+	certManagerRe = regexp.MustCompile(`(?i)\bcert-?manager\b|\b(?:k8s|kubernetes|cluster|ingress)\b`)
+)
+
+// ParseRequest reads the sources and expiry window from question
+func ParseRequest(question string) Request {
+	req := Request{Within: DefaultWindow}
+	if acmRe.MatchString(question) {
+		req.Sources = append(req.Sources, ACM)
+	}
+	if cloudflareRe.MatchString(question) {
+		req.Sources = append(req.Sources, Cloudflare)
+	}
+	if gcpRe.MatchString(question) {
+		req.Sources = append(req.Sources, GCP)
+	}
+	if certManagerRe.MatchString(question) {
+		req.Sources = append(req.Sources, CertManager)
+	}
+	if m := windowRe.FindStringSubmatch(question); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil && n > 0 {
+			unit := strings.ToLower(m[2])
+			switch {
+			case strings.HasPrefix(unit, "week"):
+				n *= 7
+			case strings.HasPrefix(unit, "month"):
+				n *= 30
+			}
+			req.Within = n
+			req.Expiring = true
+		}
+	}
+	if expiryRe.MatchString(question) || soonRe.MatchString(question) {
+		req.Expiring = true
+	}
+	return req
+}
+
+// IsCertsQuestion reports whether question asks for an inventory of TLS
+// certificates or which of them expire, rather than about cluster PKI,
+// client certificates or SSH keys
+func IsCertsQuestion(question string) bool {
+	if notCertRe.MatchString(question) {
+		return false
+	}
+	// TLS on its own is as often about protocol versions and listeners,
+	// so it counts only with an expiry word
+	if tlsRe.MatchString(question) && expiryRe.MatchString(question) {
+		return true
+	}
+	return certRe.MatchString(question) && (expiryRe.MatchString(question) || listRe.MatchString(question))
+}
@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/gcp"
+)
+
+// ToolRequirement describes a CLI that one or more plan steps shell out to
+type ToolRequirement struct {
+	Tool        string `json:"tool"`
+	Steps       []int  `json:"steps"` // 1-based plan step numbers
+	Installed   bool   `json:"installed"`
+	Installable bool   `json:"installable"` // Installer.Install can fetch it
+	InstallHint string `json:"install_hint,omitempty"`
+}
+
+// Annotation is the marker added to plan steps whose tool is missing
+func (r ToolRequirement) Annotation() string {
+	return fmt.Sprintf("requires %s (not installed)", r.Tool)
+}
+
+// lookPath is swapped out in tests
+var lookPath = exec.LookPath
+
+// toolAliases lists binaries that satisfy a tool when its usual name is absent
+var toolAliases = map[string][]string{
+	"flyctl": {"flyctl", "fly"},
+}
+
+// knownTools are the binaries plan steps name explicitly in args[0]
+var knownTools = map[string]bool{
+	"aws": true, "az": true, "docker": true, "doctl": true, "eksctl": true,
+	"flyctl": true, "fly": true, "gcloud": true, "gh": true, "glab": true, "hcloud": true, "helm": true,
+	"kubeadm": true, "kubectl": true, "oci": true, "railway": true, "vercel": true,
+}
+
+// providerTools maps a maker plan provider to the CLI its executor runs
+var providerTools = map[string]string{
+	"aws":          "aws",
+	"gcp":          "gcloud",
+	"azure":        "az",
+	"digitalocean": "doctl",
+	"hetzner":      "hcloud",
+	"oracle":       "oci",
+	"flyio":        "flyctl",
+	"vercel":       "vercel",
+	"railway":      "railway",
+	"github":       "gh",
+	"gitlab":       "glab",
+}
+
+var installHints = map[string]string{
+	"aws":     "https://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html",
+	"az":      "https://learn.microsoft.com/en-us/cli/azure/install-azure-cli",
+	"docker":  "https://docs.docker.com/get-docker/",
+	"doctl":   "https://docs.digitalocean.com/reference/doctl/how-to/install/",
+	"eksctl":  "https://eksctl.io/installation/",
+	"flyctl":  "https://fly.io/docs/flyctl/install/",
+	"gcloud":  "https://cloud.google.com/sdk/docs/install",
+	"gh":      "https://cli.github.com/",
+	"glab":    "https://gitlab.com/gitlab-org/cli",
+	"hcloud":  "https://github.com/hetznercloud/cli",
+	"helm":    "https://helm.sh/docs/intro/install/",
+	"kubeadm": "https://kubernetes.io/docs/setup/production-environment/tools/kubeadm/install-kubeadm/",
+	"kubectl": "https://kubernetes.io/docs/tasks/tools/",
+	"oci":     "https://docs.oracle.com/en-us/iaas/Content/API/SDKDocs/cliinstall.htm",
+	"railway": "https://docs.railway.com/cli",
+	"vercel":  "https://vercel.com/docs/cli",
+}
+
+// ProviderTool returns the CLI a maker plan provider executes, or "" when the
+// provider talks to its API directly. Plans without a provider are AWS plans.
+func ProviderTool(provider string) string {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if provider == "" {
+		return "aws"
+	}
+	return providerTools[provider]
+}
+
+// StepTool returns the binary a plan step runs. Steps that name a known CLI
+// in args[0] use it; anything else runs through defaultTool.
+func StepTool(args []string, defaultTool string) string {
+	if len(args) > 0 {
+		head := strings.ToLower(strings.TrimSpace(args[0]))
+		if head == "fly" {
+			return "flyctl"
+		}
+		if knownTools[head] {
+			return head
+		}
+	}
+	return defaultTool
+}
+
+// ToolAvailable reports whether tool, or one of its aliases, can be executed
+func ToolAvailable(tool string) bool {
+	switch tool {
+	case "gcloud":
+		// gcloud often lives in an SDK directory that is not on PATH
+		if _, err := lookPath("gcloud"); err == nil {
+			return true
+		}
+		_, err := gcp.FindGcloudBinary()
+		return err == nil
+	case "aws":
+		// The AWS executor honours an explicit binary override
+		if strings.TrimSpace(os.Getenv("CLANKER_AWS_CLI_PATH")) != "" {
+			return true
+		}
+	}
+	names := toolAliases[tool]
+	if len(names) == 0 {
+		names = []string{tool}
+	}
+	for _, name := range names {
+		if _, err := lookPath(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Installable reports whether Installer.Install supports tool
+func Installable(tool string) bool {
+	switch tool {
+	case "kubectl", "eksctl", "aws":
+		return true
+	default:
+		return false
+	}
+}
+
+// CheckStepTools resolves the CLI each plan step needs. tools[i] is the
+// binary for step i+1; empty entries are skipped. Requirements come back in
+// the order each tool is first used.
+func CheckStepTools(tools []string) []ToolRequirement {
+	var reqs []ToolRequirement
+	index := map[string]int{}
+	for i, tool := range tools {
+		if tool == "" {
+			continue
+		}
+		if idx, ok := index[tool]; ok {
+			reqs[idx].Steps = append(reqs[idx].Steps, i+1)
+			continue
+		}
+		index[tool] = len(reqs)
+		reqs = append(reqs, ToolRequirement{
+			Tool:        tool,
+			Steps:       []int{i + 1},
+			Installed:   ToolAvailable(tool),
+			Installable: Installable(tool),
+			InstallHint: installHints[tool],
+		})
+	}
+	return reqs
+}
+
+// MissingTools filters reqs down to tools that are not installed
+func MissingTools(reqs []ToolRequirement) []ToolRequirement {
+	var missing []ToolRequirement
+	for _, r := range reqs {
+		if !r.Installed {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
+// AlternativeCommand rewrites an eksctl step into the equivalent aws CLI
+// call so plans can still run when only the aws CLI is available. args
+// starts with the binary; ok is false when there is no faithful equivalent
+// (cluster create/delete manage CloudFormation stacks aws eks cannot).
+func AlternativeCommand(args []string) ([]string, bool) {
+	if len(args) < 3 || args[0] != "eksctl" {
+		return nil, false
+	}
+	verb, noun := args[1], args[2]
+	rest := args[3:]
+	cluster := flagValue(rest, "--cluster")
+	name := flagValue(rest, "--name")
+
+	var alt []string
+	switch {
+	case verb == "utils" && noun == "write-kubeconfig":
+		if cluster == "" {
+			cluster = name
+		}
+		if cluster == "" {
+			return nil, false
+		}
+		alt = []string{"aws", "eks", "update-kubeconfig", "--name", cluster}
+	case verb == "get" && (noun == "cluster" || noun == "clusters"):
+		if name == "" {
+			alt = []string{"aws", "eks", "list-clusters"}
+		} else {
+			alt = []string{"aws", "eks", "describe-cluster", "--name", name}
+		}
+	case verb == "get" && (noun == "nodegroup" || noun == "nodegroups" || noun == "ng"):
+		if cluster == "" {
+			return nil, false
+		}
+		if name == "" {
+			alt = []string{"aws", "eks", "list-nodegroups", "--cluster-name", cluster}
+		} else {
+			alt = []string{"aws", "eks", "describe-nodegroup", "--cluster-name", cluster, "--nodegroup-name", name}
+		}
+	case verb == "scale" && (noun == "nodegroup" || noun == "ng"):
+		nodes := flagValue(rest, "--nodes", "-N")
+		if cluster == "" || name == "" || nodes == "" {
+			return nil, false
+		}
+		scaling := "desiredSize=" + nodes
+		if v := flagValue(rest, "--nodes-min", "-m"); v != "" {
+			scaling += ",minSize=" + v
+		}
+		if v := flagValue(rest, "--nodes-max", "-M"); v != "" {
+			scaling += ",maxSize=" + v
+		}
+		alt = []string{"aws", "eks", "update-nodegroup-config", "--cluster-name", cluster, "--nodegroup-name", name, "--scaling-config", scaling}
+	case verb == "delete" && (noun == "nodegroup" || noun == "ng"):
+		if cluster == "" || name == "" {
+			return nil, false
+		}
+		alt = []string{"aws", "eks", "delete-nodegroup", "--cluster-name", cluster, "--nodegroup-name", name}
+	default:
+		return nil, false
+	}
+
+	if region := flagValue(rest, "--region"); region != "" {
+		alt = append(alt, "--region", region)
+	}
+	return alt, true
+}
+
+// flagValue returns the value of the first matching flag, accepting both
+// "--flag value" and "--flag=value"
+func flagValue(args []string, names ...string) string {
+	for i, arg := range args {
+		for _, name := range names {
+			if arg == name && i+1 < len(args) {
+				return args[i+1]
+			}
+			if strings.HasPrefix(arg, name+"=") {
+				return strings.TrimPrefix(arg, name+"=")
+			}
+		}
+	}
+	return ""
+}
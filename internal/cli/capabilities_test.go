@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func withPath(t *testing.T, installed ...string) {
+	t.Helper()
+	present := map[string]bool{}
+	for _, name := range installed {
+		present[name] = true
+	}
+	orig := lookPath
+	lookPath = func(name string) (string, error) {
+		if present[name] {
+			return "/usr/local/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+	t.Cleanup(func() { lookPath = orig })
+}
+
+func TestCheckStepTools(t *testing.T) {
+	withPath(t, "kubectl", "fly")
+
+	reqs := CheckStepTools([]string{"eksctl", "kubectl", "", "eksctl", "flyctl", "helm"})
+	if len(reqs) != 4 {
+		t.Fatalf("requirements = %+v", reqs)
+	}
+	if reqs[0].Tool != "eksctl" || !reflect.DeepEqual(reqs[0].Steps, []int{1, 4}) || reqs[0].Installed || !reqs[0].Installable {
+		t.Errorf("eksctl requirement = %+v", reqs[0])
+	}
+	if !reqs[2].Installed {
+		t.Error("flyctl should be satisfied by the fly alias")
+	}
+	if reqs[3].Installable || reqs[3].InstallHint == "" {
+		t.Errorf("helm requirement = %+v", reqs[3])
+	}
+
+	missing := MissingTools(reqs)
+	if len(missing) != 2 || missing[1].Annotation() != "requires helm (not installed)" {
+		t.Errorf("missing = %+v", missing)
+	}
+}
+
+func TestStepTool(t *testing.T) {
+	tests := []struct {
+		args        []string
+		defaultTool string
+		want        string
+	}{
+		{[]string{"eksctl", "create", "cluster"}, "", "eksctl"},
+		{[]string{"docker", "build", "."}, "az", "docker"},
+		{[]string{"group", "create"}, "az", "az"},
+		{[]string{"fly", "deploy"}, "flyctl", "flyctl"},
+		{nil, "", ""},
+	}
+	for _, tt := range tests {
+		if got := StepTool(tt.args, tt.defaultTool); got != tt.want {
+			t.Errorf("StepTool(%v, %q) = %q, want %q", tt.args, tt.defaultTool, got, tt.want)
+		}
+	}
+}
+
+func TestAlternativeCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			[]string{"eksctl", "utils", "write-kubeconfig", "--cluster=demo", "--region", "eu-west-1"},
+			[]string{"aws", "eks", "update-kubeconfig", "--name", "demo", "--region", "eu-west-1"},
+		},
+		{
+			[]string{"eksctl", "get", "cluster"},
+			[]string{"aws", "eks", "list-clusters"},
+		},
+		{
+			[]string{"eksctl", "scale", "nodegroup", "--cluster", "demo", "--name", "ng-1", "--nodes", "3", "--nodes-max", "5"},
+			[]string{"aws", "eks", "update-nodegroup-config", "--cluster-name", "demo", "--nodegroup-name", "ng-1", "--scaling-config", "desiredSize=3,maxSize=5"},
+		},
+		{
+			[]string{"eksctl", "delete", "nodegroup", "--cluster", "demo", "--name", "ng-1"},
+			[]string{"aws", "eks", "delete-nodegroup", "--cluster-name", "demo", "--nodegroup-name", "ng-1"},
+		},
+	}
+	for _, tt := range tests {
+		got, ok := AlternativeCommand(tt.args)
+		if !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AlternativeCommand(%v) = %v, %v; want %v", tt.args, got, ok, tt.want)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"eksctl", "create", "cluster", "--name", "demo"},
+		{"eksctl", "delete", "cluster", "--name", "demo"},
+		{"eksctl", "scale", "nodegroup", "--cluster", "demo"},
+		{"helm", "install", "x"},
+	} {
+		if alt, ok := AlternativeCommand(args); ok {
+			t.Errorf("AlternativeCommand(%v) = %v, want no alternative", args, alt)
+		}
+	}
+}
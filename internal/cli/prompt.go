@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -118,3 +120,47 @@ func PrintInstallationSuccess(name string) {
 func PrintInstallationError(name string, err error) {
 	fmt.Printf("Failed to install %s: %v\n", name, err)
 }
+
+// PrintMissingTools prints the degradation report for a plan whose steps
+// need CLIs that are not installed
+func PrintMissingTools(missing []ToolRequirement) {
+	fmt.Println("\nThis plan needs CLI tools that are not installed:")
+	fmt.Println()
+
+	for _, req := range missing {
+		fmt.Printf("  - %s: used by step(s) %s\n", req.Tool, JoinSteps(req.Steps))
+		if req.Installable {
+			fmt.Println("      clanker can install it")
+		} else if req.InstallHint != "" {
+			fmt.Printf("      install: %s\n", req.InstallHint)
+		}
+	}
+
+	fmt.Println()
+}
+
+// PromptForFallback asks whether to run steps through an alternative CLI.
+// alternatives maps each step number to the replacement command.
+func PromptForFallback(tool string, alternatives map[int][]string) (bool, error) {
+	steps := make([]int, 0, len(alternatives))
+	for step := range alternatives {
+		steps = append(steps, step)
+	}
+	sort.Ints(steps)
+
+	fmt.Printf("The %s steps have aws CLI equivalents:\n", tool)
+	for _, step := range steps {
+		fmt.Printf("  %d. %s\n", step, strings.Join(alternatives[step], " "))
+	}
+
+	return promptYesNo(fmt.Sprintf("Run these steps with the aws CLI instead of %s?", tool))
+}
+
+// JoinSteps formats step numbers as "1, 4, 7"
+func JoinSteps(steps []int) string {
+	parts := make([]string, len(steps))
+	for i, step := range steps {
+		parts[i] = strconv.Itoa(step)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("cloudflare.analytics")
+
 // CloudflareClient defines the interface for Cloudflare API operations
 type CloudflareClient interface {
 	RunAPI(method, endpoint, body string) (string, error)
@@ -31,17 +36,12 @@ func NewSubAgent(client CloudflareClient, debug bool) *SubAgent {
 
 // HandleQuery processes analytics-related queries
 func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[analytics] handling query: %s\n", query)
-	}
+	logger.Debugf("handling query: %s", query)
 
 	// Analyze the query
 	analysis := s.analyzeQuery(query)
 
-	if s.debug {
-		fmt.Printf("[analytics] analysis: resourceType=%s, timePeriod=%s\n",
-			analysis.ResourceType, analysis.TimePeriod)
-	}
+	logger.Debugf("analysis: resourceType=%s, timePeriod=%s", analysis.ResourceType, analysis.TimePeriod)
 
 	// Get zone ID
 	zoneID := opts.ZoneID
@@ -66,6 +66,15 @@ func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOpti
 
 	// Get analytics based on type
 	switch analysis.ResourceType {
+	case "events":
+		events, err := s.SecurityEvents(ctx, zoneID, ParseEventQuery(query), time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return &Response{
+			Type:   ResponseTypeResult,
+			Result: events.Format(),
+		}, nil
 	case "security":
 		return s.getSecurityAnalytics(ctx, zoneID, analysis.TimePeriod)
 	case "performance":
@@ -84,7 +93,9 @@ func (s *SubAgent) analyzeQuery(query string) QueryAnalysis {
 	}
 
 	// Detect resource type
-	if strings.Contains(queryLower, "security") || strings.Contains(queryLower, "threat") || strings.Contains(queryLower, "attack") {
+	if IsSecurityEventQuery(query) {
+		analysis.ResourceType = "events"
+	} else if strings.Contains(queryLower, "security") || strings.Contains(queryLower, "threat") || strings.Contains(queryLower, "attack") {
 		analysis.ResourceType = "security"
 	} else if strings.Contains(queryLower, "performance") || strings.Contains(queryLower, "speed") || strings.Contains(queryLower, "latency") {
 		analysis.ResourceType = "performance"
@@ -0,0 +1,474 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultTailInterval is how often a security event tail polls; the
+// GraphQL API allows about one query a second per token, and events land
+// there within a minute or so
+const DefaultTailInterval = 30 * time.Second
+
+// defaultEventLimit is how many individual events a listing shows
+const defaultEventLimit = 50
+
+// SecurityEvent is one firewall event from the Security Events API: a
+// request a WAF, rate limiting, bot or IP access rule acted on
+type SecurityEvent struct {
+	Action      string    `json:"action"`
+	ClientIP    string    `json:"clientIP"`
+	Country     string    `json:"clientCountryName"`
+	Method      string    `json:"clientRequestHTTPMethodName"`
+	Host        string    `json:"clientRequestHTTPHost"`
+	Path        string    `json:"clientRequestPath"`
+	Query       string    `json:"clientRequestQuery"`
+	Datetime    time.Time `json:"datetime"`
+	RayName     string    `json:"rayName"`
+	RuleID      string    `json:"ruleId"`
+	Source      string    `json:"source"`
+	Description string    `json:"description"`
+	UserAgent   string    `json:"userAgent"`
+}
+
+// EventCount is the number of events sharing one value, such as a rule
+type EventCount struct {
+	Value string
+	Label string
+	Count int64
+}
+
+// SecurityEvents is a window of security events with the rules, countries
+// and paths that account for most of them
+type SecurityEvents struct {
+	Query     EventQuery
+	Since     time.Time
+	Until     time.Time
+	Total     int64
+	Rules     []EventCount
+	Countries []EventCount
+	Paths     []EventCount
+	Events    []SecurityEvent
+}
+
+// EventQuery selects security events
+type EventQuery struct {
+	ZoneName string
+	// Actions are the firewall actions to include; all when empty
+	Actions []string
+	RuleID  string
+	// Country is an ISO 3166 alpha-2 code, e.g. CN
+	Country string
+	// Path is a request path; * matches any run of characters
+	Path   string
+	Window time.Duration
+	Limit  int
+	// Tail keeps polling for new events until interrupted
+	Tail bool
+}
+
+// challengeActions are the actions "challenged requests" covers
+var challengeActions = []string{"challenge", "managed_challenge", "jschallenge"}
+
+var (
+	securityEventRe = regexp.MustCompile(`(?i)\b(?:(?:blocked|challenged|denied|rejected|firewalled)\s+(?:requests?|traffic|visitors?|ips?|hits?)|(?:security|firewall|waf)\s+(?:events?|hits?|log|logs)|logpull|what (?:did|does) the waf block|who (?:got|is being|was) blocked)\b`)
+	tailRe          = regexp.MustCompile(`(?i)\b(?:tail|follow|live|stream|watch|continuous(?:ly)?|as they happen)\b`)
+	windowRe        = regexp.MustCompile(`(?i)\b(?:last|past|previous)\s+(?:(\d+)\s*)?(minutes?|mins?|m|hours?|hrs?|h|days?|d)\b`)
+	ruleRe          = regexp.MustCompile(`(?i)\brule(?:\s+id)?\s+["'` + "`" + `]?([0-9a-f]{6,32}|[\w-]*\d[\w-]*)\b`)
+	countryCodeRe   = regexp.MustCompile(`\b(?:from|country|in)\s+([A-Z]{2})\b`)
+	countryNameRe   = regexp.MustCompile(`(?i)\b(?:from|country|in)\s+(?:the\s+)?([a-z]+(?:\s[a-z]+)?)`)
+	pathRe          = regexp.MustCompile(`(?i)(?:^|\s)(?:path\s+|on\s+|to\s+|for\s+|hitting\s+)?["'` + "`" + `]?(/[\w./*%~-]*)`)
+	limitRe         = regexp.MustCompile(`(?i)\b(?:latest|last|top|show)\s+(\d+)\s+(?:[a-z]+\s+)?(?:events?|requests?|hits?)\b`)
+)
+
+// countryCodes maps the country names questions use to the ISO codes the
+// API filters on
+var countryCodes = map[string]string{
+	"china": "CN", "russia": "RU", "united states": "US", "usa": "US", "america": "US", "india": "IN",
+	"brazil": "BR", "germany": "DE", "france": "FR", "united kingdom": "GB", "uk": "GB", "britain": "GB",
+	"netherlands": "NL", "vietnam": "VN", "iran": "IR", "north korea": "KP", "south korea": "KR", "korea": "KR",
+	"japan": "JP", "singapore": "SG", "indonesia": "ID", "ukraine": "UA", "turkey": "TR", "canada": "CA",
+	"australia": "AU", "hong kong": "HK", "taiwan": "TW", "pakistan": "PK", "nigeria": "NG", "romania": "RO",
+	"poland": "PL", "spain": "ES", "italy": "IT", "mexico": "MX", "argentina": "AR", "thailand": "TH",
+}
+
+// IsSecurityEventQuery reports whether query asks about individual
+// requests the firewall acted on, such as "show me blocked requests in the
+// last hour", rather than about WAF configuration or traffic totals
+func IsSecurityEventQuery(query string) bool {
+	return securityEventRe.MatchString(query)
+}
+
+// ParseEventQuery reads the zone, window, filters and tail mode of a
+// security events question. The window defaults to the last hour.
+func ParseEventQuery(query string) EventQuery {
+	queryLower := strings.ToLower(query)
+	q := EventQuery{
+		ZoneName: (&SubAgent{}).extractZoneName(query),
+		Window:   time.Hour,
+		Limit:    defaultEventLimit,
+		Tail:     tailRe.MatchString(query),
+	}
+
+	switch {
+	case strings.Contains(queryLower, "managed challenge"):
+		q.Actions = []string{"managed_challenge"}
+	case strings.Contains(queryLower, "challenge"):
+		q.Actions = challengeActions
+	case strings.Contains(queryLower, "block") || strings.Contains(queryLower, "denied") || strings.Contains(queryLower, "rejected"):
+		q.Actions = []string{"block"}
+	case strings.Contains(queryLower, "logged"):
+		q.Actions = []string{"log"}
+	}
+
+	if m := windowRe.FindStringSubmatch(query); m != nil {
+		n := 1
+		if m[1] != "" {
+			n, _ = strconv.Atoi(m[1])
+		}
+		unit := time.Minute
+		switch strings.ToLower(m[2])[0] {
+		case 'h':
+			unit = time.Hour
+		case 'd':
+			unit = 24 * time.Hour
+		}
+		if n > 0 {
+			q.Window = time.Duration(n) * unit
+		}
+	}
+	if m := ruleRe.FindStringSubmatch(query); m != nil {
+		q.RuleID = m[1]
+	}
+	if m := countryCodeRe.FindStringSubmatch(query); m != nil {
+		q.Country = m[1]
+	} else {
+		for _, m := range countryNameRe.FindAllStringSubmatch(query, -1) {
+			words := strings.Fields(strings.ToLower(m[1]))
+			// Try the two-word name first, so "south korea" beats "south"
+			for n := len(words); n > 0; n-- {
+				if code, ok := countryCodes[strings.Join(words[:n], " ")]; ok {
+					q.Country = code
+					break
+				}
+			}
+			if q.Country != "" {
+				break
+			}
+		}
+	}
+	if m := pathRe.FindStringSubmatch(query); m != nil {
+		q.Path = strings.TrimRight(m[1], ".")
+	}
+	if m := limitRe.FindStringSubmatch(query); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil && n > 0 {
+			q.Limit = min(n, 1000)
+		}
+	}
+	return q
+}
+
+// Describe renders the filters of q, e.g. "blocked, country CN, path /login"
+func (q EventQuery) Describe() string {
+	var parts []string
+	switch {
+	case len(q.Actions) == 1 && q.Actions[0] == "block":
+		parts = append(parts, "blocked")
+	case len(q.Actions) > 0 && q.Actions[0] == challengeActions[0]:
+		parts = append(parts, "challenged")
+	case len(q.Actions) > 0:
+		parts = append(parts, strings.Join(q.Actions, "/"))
+	default:
+		parts = append(parts, "all actions")
+	}
+	if q.RuleID != "" {
+		parts = append(parts, "rule "+q.RuleID)
+	}
+	if q.Country != "" {
+		parts = append(parts, "country "+q.Country)
+	}
+	if q.Path != "" {
+		parts = append(parts, "path "+q.Path)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// filter returns the GraphQL filter for q's events between since and until
+func (q EventQuery) filter(since, until time.Time) map[string]interface{} {
+	f := map[string]interface{}{
+		"datetime_geq": since.UTC().Format(time.RFC3339),
+		"datetime_lt":  until.UTC().Format(time.RFC3339),
+	}
+	switch len(q.Actions) {
+	case 0:
+	case 1:
+		f["action"] = q.Actions[0]
+	default:
+		f["action_in"] = q.Actions
+	}
+	if q.RuleID != "" {
+		f["ruleId"] = q.RuleID
+	}
+	if q.Country != "" {
+		f["clientCountryName"] = q.Country
+	}
+	if q.Path != "" {
+		if strings.ContainsAny(q.Path, "*%") {
+			f["clientRequestPath_like"] = strings.ReplaceAll(q.Path, "*", "%")
+		} else {
+			f["clientRequestPath"] = q.Path
+		}
+	}
+	return f
+}
+
+const eventFields = `action clientIP clientCountryName clientRequestHTTPMethodName clientRequestHTTPHost clientRequestPath clientRequestQuery datetime rayName ruleId source description userAgent`
+
+// securityEventsQuery fetches the total, top rules, countries and paths,
+// and the latest events of a window in one request
+const securityEventsQuery = `query SecurityEvents($zoneTag: string, $filter: ZoneFirewallEventsAdaptiveFilter_InputObject, $limit: uint64!) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      total: firewallEventsAdaptiveGroups(filter: $filter, limit: 1) { count }
+      rules: firewallEventsAdaptiveGroups(filter: $filter, limit: 5, orderBy: [count_DESC]) { count dimensions { ruleId description source } }
+      countries: firewallEventsAdaptiveGroups(filter: $filter, limit: 5, orderBy: [count_DESC]) { count dimensions { clientCountryName } }
+      paths: firewallEventsAdaptiveGroups(filter: $filter, limit: 5, orderBy: [count_DESC]) { count dimensions { clientRequestPath } }
+      events: firewallEventsAdaptive(filter: $filter, limit: $limit, orderBy: [datetime_DESC]) { ` + eventFields + ` }
+    }
+  }
+}`
+
+// tailEventsQuery fetches only the events of a window, oldest first
+const tailEventsQuery = `query TailSecurityEvents($zoneTag: string, $filter: ZoneFirewallEventsAdaptiveFilter_InputObject, $limit: uint64!) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      events: firewallEventsAdaptive(filter: $filter, limit: $limit, orderBy: [datetime_ASC]) { ` + eventFields + ` }
+    }
+  }
+}`
+
+type eventGroup struct {
+	Count      int64 `json:"count"`
+	Dimensions struct {
+		RuleID      string `json:"ruleId"`
+		Description string `json:"description"`
+		Source      string `json:"source"`
+		Country     string `json:"clientCountryName"`
+		Path        string `json:"clientRequestPath"`
+	} `json:"dimensions"`
+}
+
+type eventZone struct {
+	Total     []eventGroup    `json:"total"`
+	Rules     []eventGroup    `json:"rules"`
+	Countries []eventGroup    `json:"countries"`
+	Paths     []eventGroup    `json:"paths"`
+	Events    []SecurityEvent `json:"events"`
+}
+
+// runEventQuery posts a GraphQL query for zoneID and returns its zone
+func (s *SubAgent) runEventQuery(ctx context.Context, query, zoneID string, filter map[string]interface{}, limit int) (*eventZone, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query": query,
+		"variables": map[string]interface{}{
+			"zoneTag": zoneID,
+			"filter":  filter,
+			"limit":   limit,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode security events query: %w", err)
+	}
+	result, err := s.client.RunAPIWithContext(ctx, "POST", "/graphql", string(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query security events: %w", err)
+	}
+
+	var response struct {
+		Data struct {
+			Viewer struct {
+				Zones []eventZone `json:"zones"`
+			} `json:"viewer"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal([]byte(result), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse security events: %w", err)
+	}
+	if len(response.Errors) > 0 {
+		return nil, fmt.Errorf("security events query failed: %s", response.Errors[0].Message)
+	}
+	if len(response.Data.Viewer.Zones) == 0 {
+		return &eventZone{}, nil
+	}
+	return &response.Data.Viewer.Zones[0], nil
+}
+
+// SecurityEvents returns the events q selects in the window ending at now
+func (s *SubAgent) SecurityEvents(ctx context.Context, zoneID string, q EventQuery, now time.Time) (*SecurityEvents, error) {
+	since := now.Add(-q.Window)
+	zone, err := s.runEventQuery(ctx, securityEventsQuery, zoneID, q.filter(since, now), q.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &SecurityEvents{Query: q, Since: since, Until: now, Events: zone.Events}
+	if len(zone.Total) > 0 {
+		out.Total = zone.Total[0].Count
+	}
+	for _, g := range zone.Rules {
+		label := g.Dimensions.Description
+		if g.Dimensions.Source != "" {
+			label = strings.TrimSpace(label + " [" + g.Dimensions.Source + "]")
+		}
+		out.Rules = append(out.Rules, EventCount{Value: g.Dimensions.RuleID, Label: label, Count: g.Count})
+	}
+	for _, g := range zone.Countries {
+		out.Countries = append(out.Countries, EventCount{Value: g.Dimensions.Country, Count: g.Count})
+	}
+	for _, g := range zone.Paths {
+		out.Paths = append(out.Paths, EventCount{Value: g.Dimensions.Path, Count: g.Count})
+	}
+	return out, nil
+}
+
+// TailBatch is what one poll of a security event tail found
+type TailBatch struct {
+	Events []SecurityEvent
+	Err    error
+}
+
+// TailSecurityEvents polls for new events q selects every interval,
+// starting with the last few minutes, and passes each poll's new events to
+// emit, oldest first, until ctx is done. A failed poll is passed on and
+// the tail carries on.
+func (s *SubAgent) TailSecurityEvents(ctx context.Context, zoneID string, q EventQuery, interval time.Duration, emit func(TailBatch)) error {
+	if interval <= 0 {
+		interval = DefaultTailInterval
+	}
+	cursor := time.Now().Add(-5 * time.Minute)
+	seen := map[string]time.Time{}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		// The cursor is inclusive, so events sharing its second are
+		// fetched again and dropped as seen
+		zone, err := s.runEventQuery(ctx, tailEventsQuery, zoneID, q.filter(cursor, now), 1000)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			emit(TailBatch{Err: err})
+		} else {
+			var fresh []SecurityEvent
+			for _, e := range zone.Events {
+				key := e.RayName + "/" + e.RuleID + "/" + e.Action
+				if _, ok := seen[key]; ok {
+					continue
+				}
+				seen[key] = e.Datetime
+				fresh = append(fresh, e)
+				if e.Datetime.After(cursor) {
+					cursor = e.Datetime
+				}
+			}
+			for key, at := range seen {
+				if at.Before(cursor) {
+					delete(seen, key)
+				}
+			}
+			emit(TailBatch{Events: fresh})
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Format renders the window's totals, top rules, countries and paths, and
+// its latest events
+func (e *SecurityEvents) Format() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Security events (%s), %s to %s UTC: %s\n", e.Query.Describe(),
+		e.Since.UTC().Format("2006-01-02 15:04"), e.Until.UTC().Format("15:04"), formatNumber(e.Total))
+	if e.Total == 0 && len(e.Events) == 0 {
+		return sb.String()
+	}
+
+	writeCounts := func(title string, counts []EventCount) {
+		if len(counts) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "\n%s:\n", title)
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		for _, c := range counts {
+			if c.Label == "" {
+				fmt.Fprintf(tw, "  %s\t%s\n", formatNumber(c.Count), dash(c.Value))
+				continue
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", formatNumber(c.Count), dash(c.Value), c.Label)
+		}
+		tw.Flush()
+	}
+	writeCounts("Top rules", e.Rules)
+	writeCounts("Top countries", e.Countries)
+	writeCounts("Top paths", e.Paths)
+
+	fmt.Fprintf(&sb, "\nLatest %d events:\n", len(e.Events))
+	sb.WriteString(FormatEvents(e.Events))
+	return sb.String()
+}
+
+// FormatEvents renders events as a table, one line each
+func FormatEvents(events []SecurityEvent) string {
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  TIME (UTC)\tACTION\tCOUNTRY\tCLIENT IP\tREQUEST\tRULE\tRAY ID")
+	for _, e := range events {
+		request := e.Method + " " + e.Host + e.Path + e.Query
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\t%s\n", e.Datetime.UTC().Format("01-02 15:04:05"), e.Action,
+			dash(e.Country), e.ClientIP, strings.TrimSpace(request), ruleLabel(e), dash(e.RayName))
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+// FormatEventLine renders one event for a tail, whose lines cannot be
+// aligned as a table
+func FormatEventLine(e SecurityEvent) string {
+	request := strings.TrimSpace(e.Method + " " + e.Host + e.Path + e.Query)
+	return fmt.Sprintf("%s  %-17s %-2s  %-15s  %s  %s  ray %s", e.Datetime.UTC().Format("01-02 15:04:05"), e.Action,
+		dash(e.Country), e.ClientIP, request, ruleLabel(e), dash(e.RayName))
+}
+
+// ruleLabel names the rule an event matched by its description, falling
+// back to its ID and source
+func ruleLabel(e SecurityEvent) string {
+	switch {
+	case e.Description != "":
+		return e.Description
+	case e.RuleID != "":
+		return e.RuleID + " (" + e.Source + ")"
+	}
+	return dash(e.Source)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,160 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseEventQuery(t *testing.T) {
+	tests := []struct {
+		question string
+		want     EventQuery
+	}{
+		{
+			"show me blocked requests in the last hour for example.com",
+			EventQuery{ZoneName: "example.com", Actions: []string{"block"}, Window: time.Hour, Limit: defaultEventLimit},
+		},
+		{
+			"challenged requests from china on /login in the past 6 hours for shop.io",
+			EventQuery{ZoneName: "shop.io", Actions: challengeActions, Country: "CN", Path: "/login", Window: 6 * time.Hour, Limit: defaultEventLimit},
+		},
+		{
+			"tail firewall events for rule 6179ae15870a4bb7b2d480d4843b323c from US hitting /api/* on example.com",
+			EventQuery{ZoneName: "example.com", RuleID: "6179ae15870a4bb7b2d480d4843b323c", Country: "US", Path: "/api/*", Window: time.Hour, Limit: defaultEventLimit, Tail: true},
+		},
+		{
+			"latest 200 security events for example.com over the last 2 days",
+			EventQuery{ZoneName: "example.com", Window: 48 * time.Hour, Limit: 200},
+		},
+	}
+	for _, tt := range tests {
+		if got := ParseEventQuery(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseEventQuery(%q) =\n  %+v\nwant\n  %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestIsSecurityEventQuery(t *testing.T) {
+	for _, q := range []string{
+		"show me blocked requests in the last hour",
+		"tail waf events for example.com",
+		"which IPs were challenged? show challenged requests",
+		"pull security events from logpull",
+	} {
+		if !IsSecurityEventQuery(q) {
+			t.Errorf("IsSecurityEventQuery(%q) = false", q)
+		}
+	}
+	for _, q := range []string{
+		"show firewall rules for example.com",
+		"traffic for the last day",
+		"enable under attack mode",
+	} {
+		if IsSecurityEventQuery(q) {
+			t.Errorf("IsSecurityEventQuery(%q) = true", q)
+		}
+	}
+}
+
+// fakeGraphQL answers GraphQL posts with one canned response and records
+// each request's filter
+type fakeGraphQL struct {
+	response string
+	filters  []map[string]interface{}
+}
+
+func (f *fakeGraphQL) RunAPI(method, endpoint, body string) (string, error) {
+	return f.RunAPIWithContext(context.Background(), method, endpoint, body)
+}
+
+func (f *fakeGraphQL) RunAPIWithContext(ctx context.Context, method, endpoint, body string) (string, error) {
+	var req struct {
+		Variables struct {
+			Filter map[string]interface{} `json:"filter"`
+		} `json:"variables"`
+	}
+	_ = json.Unmarshal([]byte(body), &req)
+	f.filters = append(f.filters, req.Variables.Filter)
+	return f.response, nil
+}
+
+func (f *fakeGraphQL) GetAccountID() string { return "acct" }
+
+func TestSecurityEvents(t *testing.T) {
+	fake := &fakeGraphQL{response: `{"data": {"viewer": {"zones": [{
+		"total": [{"count": 1523}],
+		"rules": [{"count": 1200, "dimensions": {"ruleId": "6179ae15", "description": "SQLi - UNION", "source": "firewallManaged"}}],
+		"countries": [{"count": 900, "dimensions": {"clientCountryName": "CN"}}],
+		"paths": [{"count": 700, "dimensions": {"clientRequestPath": "/login"}}],
+		"events": [{"action": "block", "clientIP": "203.0.113.7", "clientCountryName": "CN", "clientRequestHTTPMethodName": "POST",
+			"clientRequestHTTPHost": "example.com", "clientRequestPath": "/login", "clientRequestQuery": "?id=1",
+			"datetime": "2026-10-15T11:58:03Z", "rayName": "8d1f2a3b4c5d6e7f", "ruleId": "6179ae15", "source": "firewallManaged",
+			"description": "SQLi - UNION"}]
+	}]}}, "errors": null}`}
+	agent := NewSubAgent(fake, false)
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	q := ParseEventQuery("show blocked requests from china on /wp-* in the last 30 minutes")
+	events, err := agent.SecurityEvents(context.Background(), "zone-1", q, now)
+	if err != nil {
+		t.Fatalf("SecurityEvents: %v", err)
+	}
+	wantFilter := map[string]interface{}{
+		"datetime_geq":           "2026-10-15T11:30:00Z",
+		"datetime_lt":            "2026-10-15T12:00:00Z",
+		"action":                 "block",
+		"clientCountryName":      "CN",
+		"clientRequestPath_like": "/wp-%",
+	}
+	if !reflect.DeepEqual(fake.filters[0], wantFilter) {
+		t.Errorf("filter = %v, want %v", fake.filters[0], wantFilter)
+	}
+
+	out := events.Format()
+	for _, want := range []string{
+		"Security events (blocked, country CN, path /wp-*), 2026-10-15 11:30 to 12:00 UTC: 1.5K",
+		"1.2K  6179ae15  SQLi - UNION [firewallManaged]",
+		"900  CN\n",
+		"POST example.com/login?id=1",
+		"8d1f2a3b4c5d6e7f",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	fake.response = `{"data": null, "errors": [{"message": "zone does not have access to the path"}]}`
+	if _, err := agent.SecurityEvents(context.Background(), "zone-1", q, now); err == nil || !strings.Contains(err.Error(), "does not have access") {
+		t.Errorf("GraphQL error = %v", err)
+	}
+}
+
+func TestTailSecurityEventsDropsSeenEvents(t *testing.T) {
+	fake := &fakeGraphQL{response: `{"data": {"viewer": {"zones": [{"events": [
+		{"action": "block", "datetime": "2099-01-01T00:00:00Z", "rayName": "a1", "ruleId": "r"},
+		{"action": "block", "datetime": "2099-01-01T00:00:00Z", "rayName": "a2", "ruleId": "r"}
+	]}]}}}`}
+	agent := NewSubAgent(fake, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var batches []TailBatch
+	err := agent.TailSecurityEvents(ctx, "zone-1", EventQuery{Actions: []string{"block"}}, time.Millisecond, func(b TailBatch) {
+		batches = append(batches, b)
+		if len(batches) == 3 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("TailSecurityEvents: %v", err)
+	}
+	if len(batches) != 3 || len(batches[0].Events) != 2 || len(batches[1].Events) != 0 || len(batches[2].Events) != 0 {
+		t.Errorf("batches = %+v, want 2 events then none", batches)
+	}
+	if got := fake.filters[1]["datetime_geq"]; got != "2099-01-01T00:00:00Z" {
+		t.Errorf("second poll starts at %v, want the newest event seen", got)
+	}
+}
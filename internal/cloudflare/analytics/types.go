@@ -76,7 +76,7 @@ type Response struct {
 
 // QueryAnalysis contains the result of analyzing an analytics query
 type QueryAnalysis struct {
-	ResourceType string // traffic, security, performance
+	ResourceType string // traffic, security, performance, events
 	TimePeriod   string // 24h, 7d, 30d
 	ZoneName     string
 }
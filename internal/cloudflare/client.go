@@ -12,8 +12,12 @@ import (
 	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("cloudflare")
+
 // Client wraps Cloudflare API and CLI tools
 type Client struct {
 	accountID string
@@ -124,9 +128,7 @@ func (c *Client) RunAPIWithContext(ctx context.Context, method, endpoint, body s
 			args = append(args, "-d", body)
 		}
 
-		if c.debug {
-			fmt.Printf("[cloudflare] curl -X %s https://api.cloudflare.com/client/v4%s\n", method, endpoint)
-		}
+		logger.Debugf("curl -X %s https://api.cloudflare.com/client/v4%s", method, endpoint)
 
 		cmd := exec.CommandContext(ctx, "curl", args...)
 
@@ -189,9 +191,7 @@ func (c *Client) RunWranglerWithContext(ctx context.Context, args ...string) (st
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if c.debug {
-		fmt.Printf("[cloudflare] wrangler %s\n", strings.Join(args, " "))
-	}
+	logger.Debugf("wrangler %s", strings.Join(args, " "))
 
 	if err := cmd.Run(); err != nil {
 		stderrStr := strings.TrimSpace(stderr.String())
@@ -218,9 +218,7 @@ func (c *Client) RunCloudflaredWithContext(ctx context.Context, args ...string)
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if c.debug {
-		fmt.Printf("[cloudflare] cloudflared %s\n", strings.Join(args, " "))
-	}
+	logger.Debugf("cloudflared %s", strings.Join(args, " "))
 
 	if err := cmd.Run(); err != nil {
 		stderrStr := strings.TrimSpace(stderr.String())
@@ -4,13 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/bgdnvk/clanker/internal/convhistory"
 )
 
 // ConversationEntry represents a single Q&A exchange
@@ -64,12 +62,7 @@ func (h *ConversationHistory) AddEntry(question, answer, accountID string) {
 		AccountID: accountID,
 	}
 
-	h.Entries = append(h.Entries, entry)
-
-	// Trim old entries to keep history manageable
-	if len(h.Entries) > MaxHistoryEntries {
-		h.Entries = h.Entries[len(h.Entries)-MaxHistoryEntries:]
-	}
+	h.Entries = convhistory.Append(h.Entries, entry, MaxHistoryEntries)
 }
 
 // GetRecentContext returns recent conversation context as a formatted string
@@ -93,7 +86,7 @@ func (h *ConversationHistory) GetRecentContext(maxEntries int) string {
 			sb.WriteString("\n")
 		}
 		sb.WriteString(fmt.Sprintf("Q: %s\n", entry.Question))
-		sb.WriteString(fmt.Sprintf("A: %s\n", truncateText(entry.Answer, MaxAnswerLengthInContext)))
+		sb.WriteString(fmt.Sprintf("A: %s\n", convhistory.Truncate(entry.Answer, MaxAnswerLengthInContext)))
 	}
 
 	return sb.String()
@@ -143,86 +136,20 @@ func (h *ConversationHistory) Clear() {
 	h.LastStatus = nil
 }
 
-// Save persists the conversation history to disk
+// Save persists the conversation history to ~/.clanker/conversations/cloudflare_<accountid>.json
 func (h *ConversationHistory) Save() error {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	dir, err := getConversationDir()
-	if err != nil {
-		return err
-	}
-
-	if err := secfile.EnsurePrivateDir(dir); err != nil {
-		return fmt.Errorf("failed to create conversation directory: %w", err)
-	}
-
-	filename := filepath.Join(dir, fmt.Sprintf("cloudflare_%s.json", secfile.SafeSlug(h.AccountID)))
-	data, err := json.MarshalIndent(h, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal conversation history: %w", err)
-	}
-
-	if err := secfile.WritePrivate(filename, data); err != nil {
-		return fmt.Errorf("failed to write conversation file: %w", err)
-	}
-
-	return nil
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Entries = convhistory.FitSize(h.Entries, convhistory.MaxBytes())
+	return convhistory.Save("cloudflare", h.AccountID, h)
 }
 
 // Load loads conversation history from disk
 func (h *ConversationHistory) Load() error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-
-	dir, err := getConversationDir()
-	if err != nil {
-		return err
-	}
-
-	filename := filepath.Join(dir, fmt.Sprintf("cloudflare_%s.json", secfile.SafeSlug(h.AccountID)))
-	data, err := secfile.ReadPrivate(filename)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// No history yet, that is fine
-			return nil
-		}
-		return fmt.Errorf("failed to read conversation file: %w", err)
-	}
-
-	// Unmarshal into a temporary struct to avoid overwriting the mutex
-	var loaded struct {
-		Entries    []ConversationEntry `json:"entries"`
-		AccountID  string              `json:"account_id"`
-		LastStatus *AccountStatus      `json:"last_status,omitempty"`
-	}
-
-	if err := json.Unmarshal(data, &loaded); err != nil {
-		return fmt.Errorf("failed to parse conversation history: %w", err)
-	}
-
-	h.Entries = loaded.Entries
-	h.AccountID = loaded.AccountID
-	h.LastStatus = loaded.LastStatus
-
-	return nil
-}
-
-// getConversationDir returns the directory for storing conversation files
-func getConversationDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-	return filepath.Join(homeDir, ".clanker", "conversations"), nil
-}
-
-// truncateText truncates text to maxLen characters, adding ellipsis if truncated
-func truncateText(text string, maxLen int) string {
-	if len(text) <= maxLen {
-		return text
-	}
-	return text[:maxLen] + "..."
+	_, err := convhistory.Load("cloudflare", h.AccountID, h)
+	return err
 }
 
 // GatherAccountStatus collects basic Cloudflare account information for context
@@ -7,8 +7,13 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/bgdnvk/clanker/internal/log"
+	"github.com/bgdnvk/clanker/internal/qfilter"
 )
 
+var logger = log.New("cloudflare.dns")
+
 // CloudflareClient defines the interface for Cloudflare API operations
 type CloudflareClient interface {
 	RunAPI(method, endpoint, body string) (string, error)
@@ -32,17 +37,12 @@ func NewSubAgent(client CloudflareClient, debug bool) *SubAgent {
 
 // HandleQuery processes DNS-related queries
 func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[dns] handling query: %s\n", query)
-	}
+	logger.Debugf("handling query: %s", query)
 
 	// Analyze the query
 	analysis := s.analyzeQuery(query)
 
-	if s.debug {
-		fmt.Printf("[dns] analysis: readonly=%v, operation=%s, resourceType=%s\n",
-			analysis.IsReadOnly, analysis.Operation, analysis.ResourceType)
-	}
+	logger.Debugf("analysis: readonly=%v, operation=%s, resourceType=%s", analysis.IsReadOnly, analysis.Operation, analysis.ResourceType)
 
 	// For read-only operations, execute immediately
 	if analysis.IsReadOnly {
@@ -78,7 +78,7 @@ func (s *SubAgent) analyzeQuery(query string) QueryAnalysis {
 	analysis.Operation = s.detectOperation(queryLower)
 
 	// Determine if read-only
-	readOnlyOps := []string{"list", "get", "show", "describe", "status", "check"}
+	readOnlyOps := []string{"export", "list", "get", "show", "describe", "status", "check"}
 	for _, op := range readOnlyOps {
 		if analysis.Operation == op {
 			analysis.IsReadOnly = true
@@ -102,12 +102,18 @@ func (s *SubAgent) analyzeQuery(query string) QueryAnalysis {
 	// Extract proxied setting if mentioned
 	analysis.Proxied = s.extractProxied(queryLower)
 
+	// Compile listing qualifiers (tags) into API filters
+	analysis.Filter = qfilter.Compile(query)
+
 	return analysis
 }
 
 // detectOperation determines the operation type from the query
 func (s *SubAgent) detectOperation(queryLower string) string {
 	// Order matters - check more specific patterns first
+	if exportRe.MatchString(queryLower) {
+		return "export"
+	}
 	if strings.Contains(queryLower, "delete") || strings.Contains(queryLower, "remove") {
 		return "delete"
 	}
@@ -259,6 +265,9 @@ func (s *SubAgent) extractProxied(queryLower string) *bool {
 
 // executeReadOnly executes read-only DNS operations
 func (s *SubAgent) executeReadOnly(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*Response, error) {
+	if analysis.Operation == "export" {
+		return s.exportZone(ctx, strings.ToLower(query), analysis, opts)
+	}
 	switch analysis.ResourceType {
 	case "zone":
 		return s.listZones(ctx, opts)
@@ -269,6 +278,26 @@ func (s *SubAgent) executeReadOnly(ctx context.Context, query string, analysis Q
 	}
 }
 
+// exportZone renders every record in the zone as a BIND zone file, or as
+// JSON when the query asks for it
+func (s *SubAgent) exportZone(ctx context.Context, queryLower string, analysis QueryAnalysis, opts QueryOptions) (*Response, error) {
+	zone := analysis.ZoneName
+	if zone == "" {
+		zone = opts.ZoneName
+	}
+	if zone == "" {
+		return nil, fmt.Errorf("name the zone to export, e.g. \"export all DNS records for example.com\"")
+	}
+	result, err := s.Export(ctx, zone, exportFormat(queryLower))
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		Type:   ResponseTypeResult,
+		Result: result,
+	}, nil
+}
+
 // listZones lists all zones
 func (s *SubAgent) listZones(ctx context.Context, opts QueryOptions) (*Response, error) {
 	result, err := s.client.RunAPIWithContext(ctx, "GET", "/zones", "")
@@ -301,9 +330,14 @@ func (s *SubAgent) listRecords(ctx context.Context, analysis QueryAnalysis, opts
 		return s.listZones(ctx, opts)
 	}
 
-	endpoint := fmt.Sprintf("/zones/%s/dns_records", zoneID)
+	// Qualifiers like "tagged team:web" become record tag filters
+	params := analysis.Filter.CloudflareDNSParams()
 	if analysis.RecordType != "" {
-		endpoint += fmt.Sprintf("?type=%s", analysis.RecordType)
+		params.Set("type", analysis.RecordType)
+	}
+	endpoint := fmt.Sprintf("/zones/%s/dns_records", zoneID)
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
 	}
 
 	result, err := s.client.RunAPIWithContext(ctx, "GET", endpoint, "")
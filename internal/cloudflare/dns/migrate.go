@@ -0,0 +1,373 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// exportPageSize is how many records each page of a zone export fetches
+const exportPageSize = 500
+
+// ZoneRecords fetches every DNS record in the zone named zone, following
+// the API's pagination
+func (s *SubAgent) ZoneRecords(ctx context.Context, zone string) (string, []DNSRecord, error) {
+	zoneID, err := s.getZoneIDByName(ctx, zone)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var records []DNSRecord
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("/zones/%s/dns_records?per_page=%d&page=%d", zoneID, exportPageSize, page)
+		result, err := s.client.RunAPIWithContext(ctx, "GET", endpoint, "")
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to list records: %w", err)
+		}
+
+		var response struct {
+			Result     []DNSRecord `json:"result"`
+			ResultInfo struct {
+				TotalPages int `json:"total_pages"`
+			} `json:"result_info"`
+		}
+		if err := json.Unmarshal([]byte(result), &response); err != nil {
+			return "", nil, fmt.Errorf("failed to parse record response: %w", err)
+		}
+		records = append(records, response.Result...)
+		if page >= response.ResultInfo.TotalPages || len(response.Result) == 0 {
+			break
+		}
+	}
+	return zoneID, records, nil
+}
+
+// Export renders every record in zone as a BIND zone file, or as JSON when
+// format is "json"
+func (s *SubAgent) Export(ctx context.Context, zone, format string) (string, error) {
+	_, records, err := s.ZoneRecords(ctx, zone)
+	if err != nil {
+		return "", err
+	}
+	zoneRecords := make([]ZoneRecord, 0, len(records))
+	for _, r := range records {
+		zoneRecords = append(zoneRecords, zoneRecordFrom(r))
+	}
+	if strings.EqualFold(format, "json") {
+		return FormatJSON(zoneRecords)
+	}
+	return FormatBIND(zone, zoneRecords), nil
+}
+
+// ImportPlan diffs desired against the records zone holds now and returns
+// the maker plan that brings Cloudflare in line. Records Cloudflare has
+// and desired lacks are deleted only when prune is set.
+func (s *SubAgent) ImportPlan(ctx context.Context, zone string, desired []ZoneRecord, prune bool, question string, now time.Time) (*maker.Plan, error) {
+	zoneID, current, err := s.ZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	return DiffPlan(zone, zoneID, current, desired, prune, question, now), nil
+}
+
+// recordKey groups records that can be compared with one another
+type recordKey struct{ name, typ string }
+
+// DiffPlan returns the plan that turns current into desired: records whose
+// content changed are updated in place, and a record keeps its current TTL
+// and proxy status unless desired gives them
+func DiffPlan(zone, zoneID string, current []DNSRecord, desired []ZoneRecord, prune bool, question string, now time.Time) *maker.Plan {
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+	var keys []recordKey
+	want := map[recordKey][]ZoneRecord{}
+	have := map[recordKey][]DNSRecord{}
+	for _, d := range desired {
+		k := recordKey{d.Name, d.Type}
+		if _, ok := want[k]; !ok {
+			keys = append(keys, k)
+		}
+		want[k] = append(want[k], d)
+	}
+	var extra []recordKey
+	for _, c := range current {
+		k := recordKey{strings.ToLower(c.Name), strings.ToUpper(c.Type)}
+		if _, ok := want[k]; !ok {
+			if _, seen := have[k]; !seen {
+				extra = append(extra, k)
+			}
+		}
+		have[k] = append(have[k], c)
+	}
+	sort.Slice(extra, func(i, j int) bool {
+		if extra[i].name != extra[j].name {
+			return extra[i].name < extra[j].name
+		}
+		return extra[i].typ < extra[j].typ
+	})
+	keys = append(keys, extra...)
+
+	var deletes, updates, creates []maker.Command
+	unchanged, kept := 0, 0
+	for _, k := range keys {
+		cur, des := have[k], want[k]
+		used := make([]bool, len(cur))
+		var unmatched []ZoneRecord
+		for _, d := range des {
+			i := matchRecord(cur, used, d)
+			if i < 0 {
+				unmatched = append(unmatched, d)
+				continue
+			}
+			used[i] = true
+			if changes := settingChanges(cur[i], d); len(changes) > 0 {
+				updates = append(updates, updateCommand(zoneID, cur[i], d, strings.Join(changes, ", ")))
+			} else {
+				unchanged++
+			}
+		}
+
+		// Leftovers on both sides pair up as content changes, so a CNAME
+		// that moved is one update rather than a delete and a create
+		for i, c := range cur {
+			if used[i] {
+				continue
+			}
+			if len(unmatched) > 0 {
+				d := unmatched[0]
+				unmatched = unmatched[1:]
+				used[i] = true
+				change := fmt.Sprintf("%s -> %s", displayContent(zoneRecordFrom(c)), displayContent(d))
+				if more := settingChanges(c, d); len(more) > 0 {
+					change += ", " + strings.Join(more, ", ")
+				}
+				updates = append(updates, updateCommand(zoneID, c, d, change))
+				continue
+			}
+			if prune {
+				deletes = append(deletes, maker.Command{
+					Args:   []string{"DELETE", fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, c.ID)},
+					Reason: fmt.Sprintf("Delete %s %s %s, which the zone file does not have", c.Type, c.Name, displayContent(zoneRecordFrom(c))),
+				})
+			} else {
+				kept++
+			}
+		}
+		for _, d := range unmatched {
+			creates = append(creates, createCommand(zoneID, d))
+		}
+	}
+
+	plan := &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "cloudflare",
+		Question:  question,
+	}
+	// Deletes go first so a CNAME can take over a name other records held
+	plan.Commands = append(append(append(plan.Commands, deletes...), updates...), creates...)
+	if len(plan.Commands) == 0 {
+		plan.Summary = fmt.Sprintf("%s already matches the zone file (%d records)", zone, unchanged)
+	} else {
+		plan.Summary = fmt.Sprintf("Import %d records into %s: %d to create, %d to update, %d to delete, %d unchanged",
+			len(desired), zone, len(creates), len(updates), len(deletes), unchanged)
+	}
+	if kept > 0 {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("%d records in Cloudflare are not in the zone file and are left in place; prune to delete them", kept))
+	}
+	if len(deletes) > 0 {
+		plan.Notes = append(plan.Notes, "Applying the deletes needs --destroyer")
+	}
+	plan.Notes = append(plan.Notes, "Records keep their current TTL and proxy status unless the zone file sets them; SOA and apex NS records are managed by Cloudflare and not imported")
+	return plan
+}
+
+// matchRecord returns the first unused record in cur with d's content, or -1
+func matchRecord(cur []DNSRecord, used []bool, d ZoneRecord) int {
+	for i, c := range cur {
+		if !used[i] && sameRecord(zoneRecordFrom(c), d) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameRecord reports whether a and b hold the same data, ignoring case
+// and trailing dots in host names
+func sameRecord(a, b ZoneRecord) bool {
+	if a.Priority != nil && b.Priority != nil && *a.Priority != *b.Priority {
+		return false
+	}
+	return normalizeContent(a) == normalizeContent(b)
+}
+
+func normalizeContent(r ZoneRecord) string {
+	switch {
+	case r.Type == "TXT" || r.Type == "SPF":
+		return unquoteTXT(r.Content)
+	case hostTypes[r.Type] || r.Type == "SRV":
+		return strings.TrimSuffix(strings.ToLower(strings.Join(strings.Fields(r.Content), " ")), ".")
+	case r.Type == "AAAA" || r.Type == "CAA":
+		return strings.ToLower(strings.Join(strings.Fields(r.Content), " "))
+	}
+	return strings.TrimSpace(r.Content)
+}
+
+// settingChanges describes the TTL and proxy changes d makes to c
+func settingChanges(c DNSRecord, d ZoneRecord) []string {
+	var changes []string
+	proxied := c.Proxied
+	if d.Proxied != nil && *d.Proxied != c.Proxied {
+		proxied = *d.Proxied
+		changes = append(changes, fmt.Sprintf("proxied %s -> %s", onOff(c.Proxied), onOff(proxied)))
+	}
+	// Proxied records always use the automatic TTL
+	if d.TTL > 0 && d.TTL != c.TTL && !proxied {
+		changes = append(changes, fmt.Sprintf("TTL %s -> %s", ttlLabel(c.TTL), ttlLabel(d.TTL)))
+	}
+	return changes
+}
+
+func updateCommand(zoneID string, c DNSRecord, d ZoneRecord, change string) maker.Command {
+	ttl, proxied := c.TTL, c.Proxied
+	if d.TTL > 0 {
+		ttl = d.TTL
+	}
+	if d.Proxied != nil {
+		proxied = *d.Proxied
+	}
+	return maker.Command{
+		Args:   []string{"PUT", fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, c.ID), recordBody(d, ttl, &proxied)},
+		Reason: fmt.Sprintf("Update %s %s (%s)", d.Type, d.Name, change),
+	}
+}
+
+func createCommand(zoneID string, d ZoneRecord) maker.Command {
+	ttl := d.TTL
+	if ttl == 0 {
+		ttl = d.defaultTTL
+	}
+	if ttl == 0 {
+		ttl = 1
+	}
+	return maker.Command{
+		Args:   []string{"POST", fmt.Sprintf("/zones/%s/dns_records", zoneID), recordBody(d, ttl, d.Proxied)},
+		Reason: fmt.Sprintf("Create %s %s %s", d.Type, d.Name, displayContent(d)),
+	}
+}
+
+// recordBody is the API request body for d. SRV and CAA records go as
+// structured data, which the API requires for them.
+func recordBody(d ZoneRecord, ttl int, proxied *bool) string {
+	body := map[string]interface{}{
+		"type":    d.Type,
+		"name":    d.Name,
+		"content": d.Content,
+		"ttl":     ttl,
+	}
+	if proxied != nil {
+		body["proxied"] = *proxied
+	}
+	if d.Priority != nil && d.Type == "MX" {
+		body["priority"] = *d.Priority
+	}
+	switch f := strings.Fields(d.Content); {
+	case d.Type == "SRV" && len(f) == 3:
+		delete(body, "content")
+		weight, _ := strconv.Atoi(f[0])
+		port, _ := strconv.Atoi(f[1])
+		data := map[string]interface{}{"weight": weight, "port": port, "target": strings.TrimSuffix(f[2], ".")}
+		if d.Priority != nil {
+			data["priority"] = *d.Priority
+		}
+		body["data"] = data
+	case d.Type == "CAA" && len(f) >= 3:
+		delete(body, "content")
+		flags, _ := strconv.Atoi(f[0])
+		body["data"] = map[string]interface{}{
+			"flags": flags,
+			"tag":   f[1],
+			"value": unquoteTXT(strings.Join(f[2:], " ")),
+		}
+	}
+	out, _ := json.Marshal(body)
+	// The plan executor refuses arguments holding shell operators, and SPF,
+	// DKIM and DMARC values are full of semicolons; JSON escapes keep them
+	// intact for the API
+	return strings.NewReplacer(";", `\u003b`, "|", `\u007c`).Replace(string(out))
+}
+
+// displayContent renders a record's data for plan reasons
+func displayContent(r ZoneRecord) string {
+	content := r.Content
+	if r.Type == "TXT" || r.Type == "SPF" {
+		content = quoteTXT(content)
+	}
+	if r.Priority != nil && (r.Type == "MX" || r.Type == "SRV") {
+		content = fmt.Sprintf("%d %s", *r.Priority, content)
+	}
+	return content
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func ttlLabel(ttl int) string {
+	if ttl <= 1 {
+		return "auto"
+	}
+	return fmt.Sprintf("%d", ttl)
+}
+
+// ImportRequest is a question asking to import a zone file
+type ImportRequest struct {
+	Zone  string
+	File  string
+	Prune bool
+}
+
+var (
+	importRe   = regexp.MustCompile(`(?i)\b(?:import|migrate|sync|apply)\b`)
+	zoneFileRe = regexp.MustCompile(`(?i)(?:^|\s)["'` + "`" + `]?((?:~|\.{1,2})?/?[\w./~-]*[\w-]\.(?:zone|db|bind|txt|json))\b`)
+	pruneRe    = regexp.MustCompile(`(?i)\b(?:prune|exact(?:ly)?|mirror|delete (?:the )?(?:rest|others|extras?)|remove (?:the )?(?:rest|others|extras?))\b`)
+	exportRe   = regexp.MustCompile(`(?i)\b(?:export|dump|back ?up)\b`)
+)
+
+// ParseImportQuestion reads a zone file import from question, such as
+// "import ./example.com.zone into example.com and prune the rest"
+func ParseImportQuestion(question string) (ImportRequest, bool) {
+	if !importRe.MatchString(question) {
+		return ImportRequest{}, false
+	}
+	m := zoneFileRe.FindStringSubmatchIndex(question)
+	if m == nil {
+		return ImportRequest{}, false
+	}
+	req := ImportRequest{
+		File:  question[m[2]:m[3]],
+		Prune: pruneRe.MatchString(question),
+	}
+	// The file name often holds the zone too, so look past it
+	rest := question[:m[2]] + " " + question[m[3]:]
+	req.Zone = (&SubAgent{}).extractZoneName(rest)
+	return req, true
+}
+
+// exportFormat returns "json" when the question asks for JSON, "bind"
+// otherwise
+func exportFormat(queryLower string) string {
+	if strings.Contains(queryLower, "json") {
+		return "json"
+	}
+	return "bind"
+}
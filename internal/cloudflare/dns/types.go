@@ -1,6 +1,10 @@
 package dns
 
-import "time"
+import (
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/qfilter"
+)
 
 // Zone represents a Cloudflare DNS zone
 type Zone struct {
@@ -102,7 +106,7 @@ type Command struct {
 // QueryAnalysis contains the result of analyzing a DNS query
 type QueryAnalysis struct {
 	IsReadOnly   bool
-	Operation    string // list, get, create, update, delete
+	Operation    string // export, list, get, create, update, delete
 	ResourceType string // zone, record
 	ZoneName     string
 	RecordName   string
@@ -110,4 +114,5 @@ type QueryAnalysis struct {
 	RecordValue  string
 	TTL          int
 	Proxied      *bool
+	Filter       qfilter.Filter
 }
@@ -0,0 +1,508 @@
+package dns
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ZoneRecord is a DNS record as a zone file or JSON export carries it.
+// Names are fully qualified without the trailing dot.
+type ZoneRecord struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	// TTL is 0 when the source gives none, which keeps the current TTL on
+	// import; 1 is Cloudflare's automatic TTL
+	TTL int `json:"ttl,omitempty"`
+	// Proxied is nil when the source does not say, which keeps the current
+	// proxy status on import
+	Proxied  *bool `json:"proxied,omitempty"`
+	Priority *int  `json:"priority,omitempty"`
+
+	// defaultTTL is the zone file's $TTL, used when the import creates
+	// the record
+	defaultTTL int
+}
+
+// proxiedTag is the comment Cloudflare's own zone export puts on a record
+// to carry its proxy status, which BIND has no field for
+const proxiedTag = "cf_tags=cf-proxied:"
+
+// hostTypes are the record types whose content is a host name
+var hostTypes = map[string]bool{"CNAME": true, "NS": true, "PTR": true, "MX": true, "DNAME": true}
+
+// zoneRecordFrom converts a record from the API to its portable form
+func zoneRecordFrom(r DNSRecord) ZoneRecord {
+	proxied := r.Proxied
+	zr := ZoneRecord{
+		Name:     strings.ToLower(strings.TrimSuffix(r.Name, ".")),
+		Type:     strings.ToUpper(r.Type),
+		Content:  r.Content,
+		TTL:      r.TTL,
+		Proxied:  &proxied,
+		Priority: r.Priority,
+	}
+	switch zr.Type {
+	case "TXT":
+		zr.Content = unquoteTXT(r.Content)
+	case "SRV":
+		if r.Data != nil {
+			p := r.Data.Priority
+			zr.Priority = &p
+			zr.Content = fmt.Sprintf("%d %d %s", r.Data.Weight, r.Data.Port, r.Data.Target)
+		}
+	case "CAA":
+		if r.Data != nil {
+			zr.Content = fmt.Sprintf("%d %s %q", r.Data.Flags, r.Data.Tag, r.Data.Value)
+		}
+	}
+	return zr
+}
+
+// FormatBIND renders records as a BIND zone file for zone. Proxy status
+// rides along in a cf_tags comment, as in Cloudflare's own export, so the
+// file imports back without losing it.
+func FormatBIND(zone string, records []ZoneRecord) string {
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+	records = sortedRecords(records)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, ";; %s, %d records exported from Cloudflare\n", zone, len(records))
+	sb.WriteString(";; TTL 1 is Cloudflare's automatic TTL\n")
+	fmt.Fprintf(&sb, "$ORIGIN %s.\n\n", zone)
+	for _, r := range records {
+		ttl := r.TTL
+		if ttl == 0 {
+			ttl = 1
+		}
+		fmt.Fprintf(&sb, "%s.\t%d\tIN\t%s\t%s", r.Name, ttl, r.Type, bindRData(r))
+		if r.Proxied != nil {
+			fmt.Fprintf(&sb, " ; %s%t", proxiedTag, *r.Proxied)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// bindRData renders the data part of a zone file line
+func bindRData(r ZoneRecord) string {
+	content := r.Content
+	switch r.Type {
+	case "TXT", "SPF":
+		return quoteTXT(content)
+	case "SRV":
+		if f := strings.Fields(content); len(f) == 3 {
+			content = fmt.Sprintf("%s %s %s", f[0], f[1], fqdn(f[2]))
+		}
+	default:
+		if hostTypes[r.Type] {
+			content = fqdn(content)
+		}
+	}
+	if r.Priority != nil && (r.Type == "MX" || r.Type == "SRV") {
+		return fmt.Sprintf("%d %s", *r.Priority, content)
+	}
+	return content
+}
+
+// FormatJSON renders records as the JSON array ParseZoneFile reads back
+func FormatJSON(records []ZoneRecord) (string, error) {
+	out, err := json.MarshalIndent(sortedRecords(records), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode records: %w", err)
+	}
+	return string(out), nil
+}
+
+// sortedRecords orders records by name, type and content so exports diff
+// cleanly between runs
+func sortedRecords(records []ZoneRecord) []ZoneRecord {
+	sorted := append([]ZoneRecord(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Content < b.Content
+	})
+	return sorted
+}
+
+// ParseZoneFile reads the records of zone from a JSON export or a BIND
+// zone file, whichever data holds
+func ParseZoneFile(zone string, data []byte) ([]ZoneRecord, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return parseJSONRecords(zone, trimmed)
+	}
+	return ParseBIND(zone, bytes.NewReader(data))
+}
+
+// parseJSONRecords reads a FormatJSON array or a Cloudflare API listing
+func parseJSONRecords(zone string, data []byte) ([]ZoneRecord, error) {
+	var records []ZoneRecord
+	if data[0] == '{' {
+		var envelope struct {
+			Result []DNSRecord `json:"result"`
+		}
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON records: %w", err)
+		}
+		for _, r := range envelope.Result {
+			records = append(records, zoneRecordFrom(r))
+		}
+	} else if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON records: %w", err)
+	}
+
+	origin := strings.ToLower(strings.TrimSuffix(zone, "."))
+	for i := range records {
+		r := &records[i]
+		r.Type = strings.ToUpper(strings.TrimSpace(r.Type))
+		r.Name = absName(r.Name, origin)
+		if r.Type == "" || r.Content == "" {
+			return nil, fmt.Errorf("record %d (%s) needs a type and content", i+1, r.Name)
+		}
+		if r.Type == "TXT" {
+			r.Content = unquoteTXT(r.Content)
+		}
+	}
+	return records, nil
+}
+
+// ParseBIND reads the records of zone from a BIND zone file. SOA records
+// and NS records at the apex are skipped: Cloudflare owns both for the
+// zones it serves. A record's TTL is kept only when its own line gives
+// one; $TTL applies to records the import creates.
+func ParseBIND(zone string, r io.Reader) ([]ZoneRecord, error) {
+	origin := strings.ToLower(strings.TrimSuffix(zone, "."))
+	apex := origin
+	defaultTTL := 0
+	owner := ""
+
+	var records []ZoneRecord
+	lines, err := bindEntries(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range lines {
+		fields := e.fields
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "$ORIGIN":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: $ORIGIN needs a name", e.line)
+			}
+			origin = absName(fields[1], origin)
+			continue
+		case "$TTL":
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("line %d: $TTL needs a value", e.line)
+			}
+			ttl, ok := parseTTL(fields[1])
+			if !ok {
+				return nil, fmt.Errorf("line %d: bad $TTL %q", e.line, fields[1])
+			}
+			defaultTTL = ttl
+			continue
+		case "$INCLUDE", "$GENERATE":
+			return nil, fmt.Errorf("line %d: %s is not supported; inline the records instead", e.line, fields[0])
+		}
+
+		if !e.continued {
+			owner = absName(fields[0], origin)
+			fields = fields[1:]
+		} else if owner == "" {
+			return nil, fmt.Errorf("line %d: record has no owner name", e.line)
+		}
+
+		rec := ZoneRecord{Name: owner}
+		explicitTTL := false
+		for len(fields) > 0 {
+			if ttl, ok := parseTTL(fields[0]); ok {
+				rec.TTL, explicitTTL = ttl, true
+			} else if c := strings.ToUpper(fields[0]); c != "IN" && c != "CH" && c != "HS" {
+				break
+			}
+			fields = fields[1:]
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected a record type and data", e.line)
+		}
+		rec.Type = strings.ToUpper(fields[0])
+		data := fields[1:]
+		if !explicitTTL {
+			rec.defaultTTL = defaultTTL
+		}
+		if e.proxied != nil {
+			rec.Proxied = e.proxied
+		}
+
+		if rec.Type == "SOA" || rec.Type == "NS" && rec.Name == apex {
+			continue
+		}
+		if err := setRData(&rec, data, origin); err != nil {
+			return nil, fmt.Errorf("line %d: %w", e.line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// setRData fills rec's content and priority from the data fields of its
+// zone file line
+func setRData(rec *ZoneRecord, data []string, origin string) error {
+	switch rec.Type {
+	case "TXT", "SPF":
+		var sb strings.Builder
+		for _, d := range data {
+			sb.WriteString(unquoteTXT(d))
+		}
+		rec.Content = sb.String()
+	case "MX":
+		if len(data) != 2 {
+			return fmt.Errorf("MX needs a preference and a host")
+		}
+		p, err := strconv.Atoi(data[0])
+		if err != nil {
+			return fmt.Errorf("bad MX preference %q", data[0])
+		}
+		rec.Priority = &p
+		rec.Content = absName(data[1], origin)
+	case "SRV":
+		if len(data) != 4 {
+			return fmt.Errorf("SRV needs priority, weight, port and target")
+		}
+		p, err := strconv.Atoi(data[0])
+		if err != nil {
+			return fmt.Errorf("bad SRV priority %q", data[0])
+		}
+		rec.Priority = &p
+		rec.Content = fmt.Sprintf("%s %s %s", data[1], data[2], absName(data[3], origin))
+	case "CAA":
+		if len(data) != 3 {
+			return fmt.Errorf("CAA needs flags, a tag and a value")
+		}
+		rec.Content = fmt.Sprintf("%s %s %q", data[0], strings.ToLower(data[1]), unquoteTXT(data[2]))
+	default:
+		if len(data) == 1 && hostTypes[rec.Type] {
+			rec.Content = absName(data[0], origin)
+		} else {
+			rec.Content = strings.Join(data, " ")
+		}
+	}
+	return nil
+}
+
+// bindEntry is one logical zone file line: parentheses joined, comments
+// stripped
+type bindEntry struct {
+	line      int
+	fields    []string
+	continued bool // starts with whitespace, so reuses the previous owner
+	proxied   *bool
+}
+
+// bindEntries splits a zone file into logical lines
+func bindEntries(r io.Reader) ([]bindEntry, error) {
+	var entries []bindEntry
+	var cur *bindEntry
+	depth := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		text := scanner.Text()
+		fields, comment, opens, err := tokenizeBIND(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if cur == nil {
+			if len(fields) == 0 && opens == 0 {
+				continue
+			}
+			cur = &bindEntry{line: n, continued: text != "" && (text[0] == ' ' || text[0] == '\t')}
+		}
+		cur.fields = append(cur.fields, fields...)
+		if i := strings.Index(comment, proxiedTag); i >= 0 {
+			v := strings.HasPrefix(comment[i+len(proxiedTag):], "true")
+			cur.proxied = &v
+		}
+		depth += opens
+		if depth < 0 {
+			return nil, fmt.Errorf("line %d: unbalanced parentheses", n)
+		}
+		if depth == 0 {
+			entries = append(entries, *cur)
+			cur = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read zone file: %w", err)
+	}
+	if cur != nil {
+		return nil, fmt.Errorf("line %d: unclosed parenthesis", cur.line)
+	}
+	return entries, nil
+}
+
+// tokenizeBIND splits one physical line into fields, keeping quoted
+// strings whole, and returns the comment and the net count of opened
+// parentheses
+func tokenizeBIND(line string) (fields []string, comment string, opens int, err error) {
+	var tok strings.Builder
+	inQuote, escaped := false, false
+	flush := func() {
+		if tok.Len() > 0 {
+			fields = append(fields, tok.String())
+			tok.Reset()
+		}
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case escaped:
+			tok.WriteByte(c)
+			escaped = false
+		case c == '\\':
+			tok.WriteByte(c)
+			escaped = true
+		case inQuote:
+			tok.WriteByte(c)
+			if c == '"' {
+				inQuote = false
+				flush()
+			}
+		case c == '"':
+			flush()
+			tok.WriteByte(c)
+			inQuote = true
+		case c == ';':
+			flush()
+			return fields, line[i+1:], opens, nil
+		case c == '(':
+			flush()
+			opens++
+		case c == ')':
+			flush()
+			opens--
+		case c == ' ' || c == '\t' || c == '\r':
+			flush()
+		default:
+			tok.WriteByte(c)
+		}
+	}
+	if inQuote {
+		return nil, "", 0, fmt.Errorf("unterminated quoted string")
+	}
+	flush()
+	return fields, "", opens, nil
+}
+
+// parseTTL reads a TTL in seconds or with BIND's s, m, h, d and w units
+func parseTTL(s string) (int, bool) {
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return 0, false
+	}
+	total, num := 0, 0
+	hasUnit := false
+	for _, c := range strings.ToLower(s) {
+		if c >= '0' && c <= '9' {
+			num = num*10 + int(c-'0')
+			continue
+		}
+		mult, ok := map[rune]int{'s': 1, 'm': 60, 'h': 3600, 'd': 86400, 'w': 604800}[c]
+		if !ok {
+			return 0, false
+		}
+		total += num * mult
+		num, hasUnit = 0, true
+	}
+	if hasUnit && num != 0 {
+		return 0, false
+	}
+	return total + num, true
+}
+
+// absName qualifies a zone file name against origin: "@" is the origin
+// itself and names without a trailing dot are relative to it, unless they
+// already end in the origin, as names in JSON exports do
+func absName(name, origin string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch {
+	case name == "@" || name == "":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return strings.TrimSuffix(name, ".")
+	case origin == "" || name == origin || strings.HasSuffix(name, "."+origin):
+		return name
+	}
+	return name + "." + origin
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") || !strings.Contains(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// quoteTXT renders TXT content as zone file strings, split into the 255
+// byte chunks a single string may hold
+func quoteTXT(content string) string {
+	if strings.HasPrefix(content, `"`) && strings.HasSuffix(content, `"`) && len(content) > 1 {
+		return content
+	}
+	var parts []string
+	for len(content) > 255 {
+		parts = append(parts, strconv.Quote(content[:255]))
+		content = content[255:]
+	}
+	parts = append(parts, strconv.Quote(content))
+	return strings.Join(parts, " ")
+}
+
+// unquoteTXT joins the quoted strings of TXT data back into one value;
+// unquoted data is returned as is
+func unquoteTXT(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, `"`) {
+		return s
+	}
+	var sb strings.Builder
+	for s != "" {
+		if !strings.HasPrefix(s, `"`) {
+			sb.WriteString(s)
+			break
+		}
+		end := 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			sb.WriteString(s[1:])
+			break
+		}
+		chunk := s[1:end]
+		if u, err := strconv.Unquote(`"` + chunk + `"`); err == nil {
+			chunk = u
+		}
+		sb.WriteString(chunk)
+		s = strings.TrimSpace(s[end+1:])
+	}
+	return sb.String()
+}
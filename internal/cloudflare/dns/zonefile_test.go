@@ -0,0 +1,235 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testZone = `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1.example.net. admin.example.com. (
+		2024010101 ; serial
+		7200 3600 1209600 300 )
+@		IN	NS	ns1.example.net.
+@	300	IN	A	192.0.2.10 ; cf_tags=cf-proxied:true
+www		IN	CNAME	@
+	IN	TXT	"v=spf1 include:_spf.example.net ~all"
+_dmarc	600	TXT	"v=DMARC1; p=reject; " "rua=mailto:dmarc@example.com"
+@	IN	MX	10 mail
+mail.example.com.	3600	IN	A	192.0.2.20 ; cf_tags=cf-proxied:false
+_sip._tcp	IN	SRV	10 5 5060 sip.example.com.
+@	IN	CAA	0 issue "letsencrypt.org"
+`
+
+func intPtr(i int) *int    { return &i }
+func boolPtr(b bool) *bool { return &b }
+
+func TestParseBIND(t *testing.T) {
+	got, err := ParseBIND("example.com", strings.NewReader(testZone))
+	if err != nil {
+		t.Fatalf("ParseBIND: %v", err)
+	}
+	want := []ZoneRecord{
+		{Name: "example.com", Type: "A", Content: "192.0.2.10", TTL: 300, Proxied: boolPtr(true)},
+		{Name: "www.example.com", Type: "CNAME", Content: "example.com", defaultTTL: 3600},
+		{Name: "www.example.com", Type: "TXT", Content: "v=spf1 include:_spf.example.net ~all", defaultTTL: 3600},
+		{Name: "_dmarc.example.com", Type: "TXT", Content: "v=DMARC1; p=reject; rua=mailto:dmarc@example.com", TTL: 600},
+		{Name: "example.com", Type: "MX", Content: "mail.example.com", Priority: intPtr(10), defaultTTL: 3600},
+		{Name: "mail.example.com", Type: "A", Content: "192.0.2.20", TTL: 3600, Proxied: boolPtr(false)},
+		{Name: "_sip._tcp.example.com", Type: "SRV", Content: "5 5060 sip.example.com", Priority: intPtr(10), defaultTTL: 3600},
+		{Name: "example.com", Type: "CAA", Content: `0 issue "letsencrypt.org"`, defaultTTL: 3600},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseBIND =\n  %+v\nwant\n  %+v", got, want)
+	}
+
+	for zone, wantErr := range map[string]string{
+		"@ IN A 192.0.2.1 (":          "unclosed parenthesis",
+		`@ IN TXT "open`:              "unterminated",
+		"$INCLUDE other.zone":         "not supported",
+		"@ IN MX mail":                "MX needs",
+		"  IN A 192.0.2.1":            "no owner",
+		"@ IN":                        "expected a record type",
+		"$TTL soon\n@ IN A 192.0.2.1": "bad $TTL",
+	} {
+		if _, err := ParseBIND("example.com", strings.NewReader(zone)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("ParseBIND(%q) error = %v, want %q", zone, err, wantErr)
+		}
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	records := []ZoneRecord{
+		{Name: "www.example.com", Type: "CNAME", Content: "example.com", TTL: 1, Proxied: boolPtr(true)},
+		{Name: "example.com", Type: "MX", Content: "mail.example.com", TTL: 3600, Proxied: boolPtr(false), Priority: intPtr(10)},
+		{Name: "example.com", Type: "TXT", Content: "v=spf1 -all", TTL: 1, Proxied: boolPtr(false)},
+		{Name: "example.com", Type: "TXT", Content: strings.Repeat("k", 300), TTL: 1, Proxied: boolPtr(false)},
+	}
+	bind := FormatBIND("example.com", records)
+	for _, want := range []string{
+		"$ORIGIN example.com.",
+		"www.example.com.\t1\tIN\tCNAME\texample.com. ; cf_tags=cf-proxied:true",
+		"example.com.\t3600\tIN\tMX\t10 mail.example.com. ; cf_tags=cf-proxied:false",
+		`"v=spf1 -all"`,
+	} {
+		if !strings.Contains(bind, want) {
+			t.Errorf("BIND export missing %q:\n%s", want, bind)
+		}
+	}
+	back, err := ParseZoneFile("example.com", []byte(bind))
+	if err != nil {
+		t.Fatalf("ParseZoneFile(bind): %v", err)
+	}
+	if !reflect.DeepEqual(back, sortedRecords(records)) {
+		t.Errorf("BIND round trip =\n  %+v\nwant\n  %+v", back, sortedRecords(records))
+	}
+
+	js, err := FormatJSON(records)
+	if err != nil {
+		t.Fatalf("FormatJSON: %v", err)
+	}
+	back, err = ParseZoneFile("example.com", []byte(js))
+	if err != nil {
+		t.Fatalf("ParseZoneFile(json): %v", err)
+	}
+	if !reflect.DeepEqual(back, sortedRecords(records)) {
+		t.Errorf("JSON round trip =\n  %+v\nwant\n  %+v", back, sortedRecords(records))
+	}
+}
+
+func TestDiffPlan(t *testing.T) {
+	current := []DNSRecord{
+		{ID: "r1", Type: "A", Name: "example.com", Content: "192.0.2.10", TTL: 1, Proxied: true},
+		{ID: "r2", Type: "CNAME", Name: "www.example.com", Content: "old.example.net", TTL: 1, Proxied: true},
+		{ID: "r3", Type: "MX", Name: "example.com", Content: "mail.example.com", TTL: 300, Priority: intPtr(10)},
+		{ID: "r4", Type: "TXT", Name: "legacy.example.com", Content: `"stale"`, TTL: 1},
+		{ID: "r5", Type: "TXT", Name: "_dmarc.example.com", Content: `"v=DMARC1; p=none"`, TTL: 1},
+	}
+	desired, err := ParseBIND("example.com", strings.NewReader(`$TTL 600
+@	IN	A	192.0.2.10
+www	IN	CNAME	new.example.net.
+@	7200	IN	MX	10 mail
+_dmarc	IN	TXT	"v=DMARC1; p=reject"
+api	IN	A	192.0.2.30 ; cf_tags=cf-proxied:true
+`))
+	if err != nil {
+		t.Fatalf("ParseBIND: %v", err)
+	}
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	plan := DiffPlan("example.com", "z1", current, desired, false, "import", now)
+	if plan.Provider != "cloudflare" {
+		t.Errorf("provider = %q", plan.Provider)
+	}
+	want := [][]string{
+		// The CNAME keeps proxying and its automatic TTL
+		{"PUT", "/zones/z1/dns_records/r2", `{"content":"new.example.net","name":"www.example.com","proxied":true,"ttl":1,"type":"CNAME"}`},
+		{"PUT", "/zones/z1/dns_records/r3", `{"content":"mail.example.com","name":"example.com","priority":10,"proxied":false,"ttl":7200,"type":"MX"}`},
+		{"PUT", "/zones/z1/dns_records/r5", `{"content":"v=DMARC1\u003b p=reject","name":"_dmarc.example.com","proxied":false,"ttl":1,"type":"TXT"}`},
+		{"POST", "/zones/z1/dns_records", `{"content":"192.0.2.30","name":"api.example.com","proxied":true,"ttl":600,"type":"A"}`},
+	}
+	var got [][]string
+	for _, c := range plan.Commands {
+		got = append(got, c.Args)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("commands =\n  %v\nwant\n  %v", got, want)
+	}
+	if plan.Summary != "Import 5 records into example.com: 1 to create, 3 to update, 0 to delete, 1 unchanged" {
+		t.Errorf("summary = %q", plan.Summary)
+	}
+	if !strings.Contains(plan.Commands[1].Reason, "TTL 300 -> 7200") {
+		t.Errorf("MX reason = %q", plan.Commands[1].Reason)
+	}
+	if !strings.Contains(plan.Notes[0], "1 records in Cloudflare are not in the zone file") {
+		t.Errorf("notes = %v", plan.Notes)
+	}
+
+	pruned := DiffPlan("example.com", "z1", current, desired, true, "import", now)
+	if got := pruned.Commands[0].Args; !reflect.DeepEqual(got, []string{"DELETE", "/zones/z1/dns_records/r4"}) {
+		t.Errorf("first pruned command = %v", got)
+	}
+
+	same := DiffPlan("example.com", "z1", current[:1], desired[:1], false, "import", now)
+	if len(same.Commands) != 0 || same.Summary != "example.com already matches the zone file (1 records)" {
+		t.Errorf("no-op plan = %d commands, %q", len(same.Commands), same.Summary)
+	}
+}
+
+func TestParseImportQuestion(t *testing.T) {
+	tests := []struct {
+		question string
+		want     ImportRequest
+		ok       bool
+	}{
+		{"import ./example.com.zone into example.com", ImportRequest{Zone: "example.com", File: "./example.com.zone"}, true},
+		{"migrate zones/shop.io.db to cloudflare zone shop.io and prune the rest", ImportRequest{Zone: "shop.io", File: "zones/shop.io.db", Prune: true}, true},
+		{"sync ~/dns/records.json with example.org exactly", ImportRequest{Zone: "example.org", File: "~/dns/records.json", Prune: true}, true},
+		{"import dns records for example.com", ImportRequest{}, false},
+		{"export all dns records for example.com", ImportRequest{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseImportQuestion(tt.question)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseImportQuestion(%q) = %+v, %v; want %+v, %v", tt.question, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// fakeCloudflare serves zone lookups and pages of records
+type fakeCloudflare struct {
+	pages []string
+}
+
+func (f *fakeCloudflare) RunAPI(method, endpoint, body string) (string, error) {
+	return f.RunAPIWithContext(context.Background(), method, endpoint, body)
+}
+
+func (f *fakeCloudflare) RunAPIWithContext(ctx context.Context, method, endpoint, body string) (string, error) {
+	if strings.HasPrefix(endpoint, "/zones?name=") {
+		return `{"success": true, "result": [{"id": "z1", "name": "example.com"}]}`, nil
+	}
+	for i, page := range f.pages {
+		if strings.HasSuffix(endpoint, fmt.Sprintf("&page=%d", i+1)) {
+			return page, nil
+		}
+	}
+	return "", fmt.Errorf("unexpected %s %s", method, endpoint)
+}
+
+func (f *fakeCloudflare) GetAccountID() string { return "acct" }
+
+func TestExportFollowsPages(t *testing.T) {
+	fake := &fakeCloudflare{pages: []string{
+		`{"success": true, "result": [{"id": "r1", "type": "A", "name": "example.com", "content": "192.0.2.10", "ttl": 1, "proxied": true}],
+		  "result_info": {"page": 1, "total_pages": 2}}`,
+		`{"success": true, "result": [{"id": "r2", "type": "SRV", "name": "_sip._tcp.example.com", "content": "5 5060 sip.example.com", "ttl": 300,
+		  "data": {"priority": 10, "weight": 5, "port": 5060, "target": "sip.example.com"}}],
+		  "result_info": {"page": 2, "total_pages": 2}}`,
+	}}
+	agent := NewSubAgent(fake, false)
+	resp, err := agent.HandleQuery(context.Background(), "export all dns records for example.com", QueryOptions{})
+	if err != nil {
+		t.Fatalf("HandleQuery: %v", err)
+	}
+	for _, want := range []string{
+		"example.com.\t1\tIN\tA\t192.0.2.10 ; cf_tags=cf-proxied:true",
+		"_sip._tcp.example.com.\t300\tIN\tSRV\t10 5 5060 sip.example.com. ; cf_tags=cf-proxied:false",
+	} {
+		if !strings.Contains(resp.Result, want) {
+			t.Errorf("export missing %q:\n%s", want, resp.Result)
+		}
+	}
+
+	out, err := agent.Export(context.Background(), "example.com", "json")
+	if err != nil {
+		t.Fatalf("Export(json): %v", err)
+	}
+	if !strings.Contains(out, `"priority": 10`) || !strings.Contains(out, `"content": "5 5060 sip.example.com"`) {
+		t.Errorf("JSON export =\n%s", out)
+	}
+}
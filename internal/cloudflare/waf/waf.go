@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("cloudflare.waf")
+
 // CloudflareClient defines the interface for Cloudflare API operations
 type CloudflareClient interface {
 	RunAPI(method, endpoint, body string) (string, error)
@@ -31,17 +35,12 @@ func NewSubAgent(client CloudflareClient, debug bool) *SubAgent {
 
 // HandleQuery processes WAF-related queries
 func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[waf] handling query: %s\n", query)
-	}
+	logger.Debugf("handling query: %s", query)
 
 	// Analyze the query
 	analysis := s.analyzeQuery(query)
 
-	if s.debug {
-		fmt.Printf("[waf] analysis: readonly=%v, operation=%s, resourceType=%s\n",
-			analysis.IsReadOnly, analysis.Operation, analysis.ResourceType)
-	}
+	logger.Debugf("analysis: readonly=%v, operation=%s, resourceType=%s", analysis.IsReadOnly, analysis.Operation, analysis.ResourceType)
 
 	// For read-only operations, execute immediately
 	if analysis.IsReadOnly {
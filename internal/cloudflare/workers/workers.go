@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("cloudflare.workers")
+
 // CloudflareClient defines the interface for Cloudflare operations
 type CloudflareClient interface {
 	RunAPI(method, endpoint, body string) (string, error)
@@ -33,17 +37,12 @@ func NewSubAgent(client CloudflareClient, debug bool) *SubAgent {
 
 // HandleQuery processes Workers-related queries
 func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[workers] handling query: %s\n", query)
-	}
+	logger.Debugf("handling query: %s", query)
 
 	// Analyze the query
 	analysis := s.analyzeQuery(query)
 
-	if s.debug {
-		fmt.Printf("[workers] analysis: readonly=%v, operation=%s, resourceType=%s\n",
-			analysis.IsReadOnly, analysis.Operation, analysis.ResourceType)
-	}
+	logger.Debugf("analysis: readonly=%v, operation=%s, resourceType=%s", analysis.IsReadOnly, analysis.Operation, analysis.ResourceType)
 
 	// For read-only operations, execute immediately
 	if analysis.IsReadOnly {
@@ -5,8 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("cloudflare.zerotrust")
+
 // CloudflareClient defines the interface for Cloudflare operations
 type CloudflareClient interface {
 	RunAPI(method, endpoint, body string) (string, error)
@@ -32,17 +36,12 @@ func NewSubAgent(client CloudflareClient, debug bool) *SubAgent {
 
 // HandleQuery processes Zero Trust related queries
 func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[zerotrust] handling query: %s\n", query)
-	}
+	logger.Debugf("handling query: %s", query)
 
 	// Analyze the query
 	analysis := s.analyzeQuery(query)
 
-	if s.debug {
-		fmt.Printf("[zerotrust] analysis: readonly=%v, operation=%s, resourceType=%s\n",
-			analysis.IsReadOnly, analysis.Operation, analysis.ResourceType)
-	}
+	logger.Debugf("analysis: readonly=%v, operation=%s, resourceType=%s", analysis.IsReadOnly, analysis.Operation, analysis.ResourceType)
 
 	// Get account ID
 	accountID := opts.AccountID
@@ -0,0 +1,156 @@
+// Package contextcache keeps the cloud inventory context gathered for
+// clanker ask under ~/.clanker/cache/<provider>/<profile>.json, so asking
+// again within context_cache.ttl reuses the listings instead of re-querying
+// every API. Entries are keyed by the normalized question, since that is what
+// decides which services a provider lists.
+package contextcache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/spf13/viper"
+)
+
+// DefaultTTL is how long cached context is reused when context_cache.ttl is
+// not set
+const DefaultTTL = 10 * time.Minute
+
+// maxEntries bounds each file; the oldest entries are dropped first
+const maxEntries = 50
+
+// Entry is one cached context lookup
+type Entry struct {
+	Question  string    `json:"question"`
+	Content   string    `json:"content"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+type file struct {
+	Entries map[string]Entry `json:"entries"`
+}
+
+var mu sync.Mutex
+
+// Dir returns ~/.clanker/cache
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "cache"), nil
+}
+
+// Path returns the cache file for provider and profile
+func Path(provider, profile string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, secfile.SafeSlug(provider), secfile.SafeSlug(profile)+".json"), nil
+}
+
+// Enabled reports whether context caching is on (context_cache.disabled)
+func Enabled() bool {
+	return !viper.GetBool("context_cache.disabled")
+}
+
+// Refresh reports whether this run should skip cached context
+// (--refresh-context)
+func Refresh() bool {
+	return viper.GetBool("context_cache.refresh")
+}
+
+// TTL returns context_cache.ttl, or DefaultTTL
+func TTL() time.Duration {
+	if ttl := viper.GetDuration("context_cache.ttl"); ttl > 0 {
+		return ttl
+	}
+	return DefaultTTL
+}
+
+// Key normalizes question so rephrasings that differ only in case, spacing
+// or trailing punctuation share an entry
+func Key(question string) string {
+	return strings.TrimRight(strings.Join(strings.Fields(strings.ToLower(question)), " "), "?!.")
+}
+
+// Get returns the cached context for question when it is younger than ttl
+func Get(provider, profile, question string, ttl time.Duration) (Entry, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	f, err := load(provider, profile)
+	if err != nil {
+		return Entry{}, false
+	}
+	e, ok := f.Entries[Key(question)]
+	if !ok || time.Since(e.FetchedAt) > ttl {
+		return Entry{}, false
+	}
+	return e, true
+}
+
+// Put stores content as the context for question
+func Put(provider, profile, question, content string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	f, err := load(provider, profile)
+	if err != nil {
+		f = &file{}
+	}
+	if f.Entries == nil {
+		f.Entries = make(map[string]Entry)
+	}
+	f.Entries[Key(question)] = Entry{Question: question, Content: content, FetchedAt: time.Now().UTC()}
+	for len(f.Entries) > maxEntries {
+		oldest := ""
+		for k, e := range f.Entries {
+			if oldest == "" || e.FetchedAt.Before(f.Entries[oldest].FetchedAt) {
+				oldest = k
+			}
+		}
+		delete(f.Entries, oldest)
+	}
+
+	path, err := Path(provider, profile)
+	if err != nil {
+		return err
+	}
+	if err := secfile.EnsurePrivateDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal context cache: %w", err)
+	}
+	if err := secfile.WritePrivate(path, data); err != nil {
+		return fmt.Errorf("failed to write context cache: %w", err)
+	}
+	return nil
+}
+
+func load(provider, profile string) (*file, error) {
+	path, err := Path(provider, profile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := secfile.ReadPrivate(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &file{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse context cache %s: %w", path, err)
+	}
+	return &f, nil
+}
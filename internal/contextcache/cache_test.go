@@ -0,0 +1,73 @@
+package contextcache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestPutGet(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if _, ok := Get("aws", "prod", "list ec2 instances", time.Minute); ok {
+		t.Fatal("empty cache should miss")
+	}
+	if err := Put("aws", "prod", "List EC2  instances?", "EC2 Instances:\ni-123"); err != nil {
+		t.Fatal(err)
+	}
+
+	e, ok := Get("aws", "prod", "list ec2 instances", time.Minute)
+	if !ok || e.Content != "EC2 Instances:\ni-123" {
+		t.Fatalf("Get() = %+v, %v; rephrased question should hit", e, ok)
+	}
+	if _, ok := Get("aws", "staging", "list ec2 instances", time.Minute); ok {
+		t.Error("another profile should not share the entry")
+	}
+	if _, ok := Get("aws", "prod", "list ec2 instances", 0); ok {
+		t.Error("an entry older than the TTL should miss")
+	}
+
+	info, err := os.Stat(filepath.Join(home, ".clanker", "cache", "aws", "prod.json"))
+	if err != nil {
+		t.Fatalf("expected cache file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("cache file perms = %o, want 600", perm)
+	}
+}
+
+func TestPutDropsOldestEntries(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for i := 0; i <= maxEntries; i++ {
+		if err := Put("gcp", "proj", fmt.Sprintf("question %d", i), "content"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := load("gcp", "proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Entries) != maxEntries {
+		t.Errorf("cache holds %d entries, want %d", len(f.Entries), maxEntries)
+	}
+	if _, ok := f.Entries[Key("question 0")]; ok {
+		t.Error("the oldest entry should have been dropped")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	if got := TTL(); got != DefaultTTL {
+		t.Errorf("TTL() = %s, want %s", got, DefaultTTL)
+	}
+	viper.Set("context_cache.ttl", "90s")
+	t.Cleanup(func() { viper.Set("context_cache.ttl", nil) })
+	if got := TTL(); got != 90*time.Second {
+		t.Errorf("TTL() = %s, want 90s", got)
+	}
+}
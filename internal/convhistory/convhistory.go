@@ -0,0 +1,208 @@
+// Package convhistory stores the conversation history behind each
+// provider's ask mode. Every provider keeps its own document shape, but the
+// files all live in ~/.clanker/conversations/<provider>_<scope>.json, are
+// written atomically with 0600 perms, have credentials redacted, are kept
+// under a size limit, and can be encrypted at rest with conversations.encrypt.
+package convhistory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bgdnvk/clanker/internal/redact"
+	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/spf13/viper"
+)
+
+// DefaultMaxBytes is the size conversation entries are trimmed to when
+// conversations.max_bytes is not set
+const DefaultMaxBytes = 256 << 10
+
+var (
+	pathLocks   = map[string]*sync.Mutex{}
+	pathLocksMu sync.Mutex
+)
+
+// lockFor serializes Load and Save per file so concurrent asks against the
+// same scope don't interleave the temp-file rename with a read
+func lockFor(path string) *sync.Mutex {
+	pathLocksMu.Lock()
+	defer pathLocksMu.Unlock()
+	if m, ok := pathLocks[path]; ok {
+		return m
+	}
+	m := &sync.Mutex{}
+	pathLocks[path] = m
+	return m
+}
+
+// Dir returns ~/.clanker/conversations
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "conversations"), nil
+}
+
+// Path returns the conversation file for provider and scope
+func Path(provider, scope string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s_%s.json", provider, secfile.SafeSlug(scope))), nil
+}
+
+// legacyPath is where some providers kept history before it moved under
+// ~/.clanker/conversations
+func legacyPath(provider, scope string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", fmt.Sprintf("%s-%s.json", provider, secfile.SafeSlug(scope))), nil
+}
+
+// Load reads provider's conversation for scope into doc and reports whether
+// there was one. History from a legacy location is read when the current
+// file does not exist yet; the next Save moves it. A missing file is not an
+// error. Encrypted history is decrypted with the local key, and plaintext
+// history loads as is whether or not encryption is on.
+func Load(provider, scope string, doc any) (bool, error) {
+	path, err := Path(provider, scope)
+	if err != nil {
+		return false, err
+	}
+	lock := lockFor(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	data, err := secfile.ReadPrivate(path)
+	if errors.Is(err, os.ErrNotExist) {
+		legacy, lerr := legacyPath(provider, scope)
+		if lerr != nil {
+			return false, lerr
+		}
+		data, err = secfile.ReadPrivate(legacy)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read conversation file: %w", err)
+	}
+
+	data, err = decrypt(data)
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return false, fmt.Errorf("failed to parse conversation history: %w", err)
+	}
+	return true, nil
+}
+
+// Save writes doc as provider's conversation for scope. The write is atomic,
+// so a crash leaves either the old or the new file, never half of one.
+func Save(provider, scope string, doc any) error {
+	path, err := Path(provider, scope)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation history: %w", err)
+	}
+	if data, err = redact.JSON(data); err != nil {
+		return fmt.Errorf("failed to redact conversation history: %w", err)
+	}
+	if viper.GetBool("conversations.encrypt") {
+		if data, err = encrypt(data); err != nil {
+			return err
+		}
+	}
+
+	lock := lockFor(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	dir := filepath.Dir(path)
+	if err := secfile.EnsurePrivateDir(dir); err != nil {
+		return fmt.Errorf("failed to create conversation directory: %w", err)
+	}
+	// os.CreateTemp creates files at 0600, which the rename keeps
+	tmp, err := os.CreateTemp(dir, provider+"_*.json.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp conversation file: %w", err)
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("failed to write temp conversation file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("failed to write temp conversation file: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("failed to rename conversation file: %w", err)
+	}
+
+	if legacy, err := legacyPath(provider, scope); err == nil {
+		_ = os.Remove(legacy)
+	}
+	return nil
+}
+
+// Append adds entry and keeps the newest maxEntries entries
+func Append[E any](entries []E, entry E, maxEntries int) []E {
+	entries = append(entries, entry)
+	if maxEntries > 0 && len(entries) > maxEntries {
+		entries = entries[len(entries)-maxEntries:]
+	}
+	return entries
+}
+
+// FitSize drops the oldest entries until the rest encode to at most maxBytes.
+// The newest entry is always kept.
+func FitSize[E any](entries []E, maxBytes int) []E {
+	if maxBytes <= 0 || len(entries) == 0 {
+		return entries
+	}
+	sizes := make([]int, len(entries))
+	total := 0
+	for i, e := range entries {
+		data, _ := json.Marshal(e)
+		sizes[i] = len(data) + 1
+		total += sizes[i]
+	}
+	start := 0
+	for total > maxBytes && start < len(entries)-1 {
+		total -= sizes[start]
+		start++
+	}
+	return entries[start:]
+}
+
+// MaxBytes returns conversations.max_bytes, or DefaultMaxBytes
+func MaxBytes() int {
+	if n := viper.GetInt("conversations.max_bytes"); n > 0 {
+		return n
+	}
+	return DefaultMaxBytes
+}
+
+// Truncate shortens text to maxLen bytes, adding an ellipsis when it cuts
+func Truncate(text string, maxLen int) string {
+	if len(text) <= maxLen {
+		return text
+	}
+	return text[:maxLen] + "..."
+}
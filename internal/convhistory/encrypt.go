@@ -0,0 +1,138 @@
+package convhistory
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+)
+
+// KeyEnv overrides the generated key file with a passphrase
+const KeyEnv = "CLANKER_HISTORY_KEY"
+
+const envelopeAlgorithm = "aes-256-gcm"
+
+// envelope is the on-disk form of an encrypted conversation file
+type envelope struct {
+	Encrypted string `json:"encrypted"`
+	Nonce     []byte `json:"nonce"`
+	Data      []byte `json:"data"`
+}
+
+// keyPath returns ~/.clanker/conversations.key, kept outside the
+// conversations directory so copying that directory does not copy the key
+func keyPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "conversations.key"), nil
+}
+
+// encryptionKey derives the key from CLANKER_HISTORY_KEY, or reads the key
+// file, generating it first when create is set
+func encryptionKey(create bool) ([]byte, error) {
+	if passphrase := os.Getenv(KeyEnv); passphrase != "" {
+		sum := sha256.Sum256([]byte(passphrase))
+		return sum[:], nil
+	}
+	path, err := keyPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := secfile.ReadPrivate(path)
+	if err == nil {
+		key, derr := hex.DecodeString(strings.TrimSpace(string(data)))
+		if derr != nil || len(key) != 32 {
+			return nil, fmt.Errorf("conversation key %s is corrupt", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read conversation key: %w", err)
+	}
+	if !create {
+		return nil, fmt.Errorf("conversation history is encrypted but %s is missing (or set %s)", path, KeyEnv)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate conversation key: %w", err)
+	}
+	if err := secfile.EnsurePrivateDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	if err := secfile.WritePrivate(path, []byte(hex.EncodeToString(key)+"\n")); err != nil {
+		return nil, fmt.Errorf("failed to write conversation key: %w", err)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encrypt(plaintext []byte) ([]byte, error) {
+	key, err := encryptionKey(true)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(envelope{
+		Encrypted: envelopeAlgorithm,
+		Nonce:     nonce,
+		Data:      gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+}
+
+// decrypt returns data unchanged unless it is an encrypted envelope, so
+// plaintext history keeps loading after encryption is turned on or off
+func decrypt(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(`"encrypted"`)) {
+		return data, nil
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil || env.Encrypted == "" {
+		return data, nil
+	}
+	if env.Encrypted != envelopeAlgorithm {
+		return nil, fmt.Errorf("unsupported conversation encryption %q", env.Encrypted)
+	}
+	key, err := encryptionKey(false)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("conversation file has an invalid nonce")
+	}
+	plaintext, err := gcm.Open(nil, env.Nonce, env.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt conversation history (wrong key?): %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,288 @@
+// Package daemon implements the optional warm `clanker daemon`. The daemon
+// is a long-lived clanker process listening on a unix socket; short-lived
+// CLI invocations forward their argv to it and stream the output back.
+// Because the process stays up, provider caches (AWS credentials, kube
+// discovery, AI HTTP keep-alive pools) survive between commands instead of
+// being rebuilt on every run.
+//
+// Commands run one at a time: the runner swaps the process-wide stdout,
+// stderr, working directory, and environment for the duration of a request.
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+)
+
+// dialTimeout keeps the forwarding probe cheap when no daemon is running
+const dialTimeout = 200 * time.Millisecond
+
+// Request is sent by the CLI to run one command inside the daemon
+type Request struct {
+	Args    []string `json:"args,omitempty"`
+	Cwd     string   `json:"cwd,omitempty"`
+	Env     []string `json:"env,omitempty"`
+	Control string   `json:"control,omitempty"` // "ping" or "shutdown" instead of Args
+}
+
+// Frame is one line of the daemon's streamed reply
+type Frame struct {
+	Stream string `json:"stream,omitempty"` // stdout or stderr
+	Data   string `json:"data,omitempty"`
+	Done   bool   `json:"done,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RunFunc executes a CLI invocation, writing its output to stdout/stderr
+type RunFunc func(args []string, stdout, stderr io.Writer) error
+
+// SocketPath returns the default daemon socket, ~/.clanker/daemon.sock
+func SocketPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".clanker", "daemon.sock"), nil
+}
+
+// Server accepts forwarded commands on a unix socket
+type Server struct {
+	socketPath string
+	run        RunFunc
+	debug      bool
+	started    time.Time
+
+	mu       sync.Mutex // serialises runs; see package doc
+	served   int
+	listener net.Listener
+	cancel   context.CancelFunc
+}
+
+// NewServer creates a daemon server bound to socketPath
+func NewServer(socketPath string, run RunFunc, debug bool) *Server {
+	return &Server{
+		socketPath: socketPath,
+		run:        run,
+		debug:      debug,
+	}
+}
+
+// Serve listens until ctx is cancelled or a shutdown request arrives. A
+// stale socket left by a crashed daemon is replaced; a live one is an error.
+func (s *Server) Serve(ctx context.Context) error {
+	if err := secfile.EnsurePrivateDir(filepath.Dir(s.socketPath)); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	if Running(s.socketPath) {
+		return fmt.Errorf("a clanker daemon is already listening on %s", s.socketPath)
+	}
+	_ = os.Remove(s.socketPath)
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.socketPath, err)
+	}
+	if err := os.Chmod(s.socketPath, secfile.PrivateFileMode); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	defer os.Remove(s.socketPath)
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.listener = listener
+	s.cancel = cancel
+	s.started = time.Now()
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accept failed: %w", err)
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var req Request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		writeFrame(conn, Frame{Done: true, Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+
+	switch req.Control {
+	case "ping":
+		s.mu.Lock()
+		status := fmt.Sprintf("pid %d, up %s, %d commands served", os.Getpid(), time.Since(s.started).Round(time.Second), s.served)
+		s.mu.Unlock()
+		writeFrame(conn, Frame{Stream: "stdout", Data: status + "\n"})
+		writeFrame(conn, Frame{Done: true})
+		return
+	case "shutdown":
+		writeFrame(conn, Frame{Done: true})
+		s.cancel()
+		return
+	case "":
+	default:
+		writeFrame(conn, Frame{Done: true, Error: fmt.Sprintf("unknown control %q", req.Control)})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.served++
+
+	if s.debug {
+		fmt.Fprintf(os.Stderr, "[daemon] running %v\n", req.Args)
+	}
+
+	restore, err := applyRequestEnv(req)
+	if err != nil {
+		writeFrame(conn, Frame{Done: true, Error: err.Error()})
+		return
+	}
+	defer restore()
+
+	frameMu := &sync.Mutex{}
+	stdout := &frameWriter{conn: conn, stream: "stdout", mu: frameMu}
+	stderr := &frameWriter{conn: conn, stream: "stderr", mu: frameMu}
+	runErr := s.run(req.Args, stdout, stderr)
+
+	done := Frame{Done: true}
+	if runErr != nil {
+		done.Error = runErr.Error()
+	}
+	frameMu.Lock()
+	writeFrame(conn, done)
+	frameMu.Unlock()
+}
+
+// applyRequestEnv switches the process to the caller's cwd and environment
+// and returns a function restoring the daemon's own
+func applyRequestEnv(req Request) (func(), error) {
+	origEnv := os.Environ()
+	origCwd, _ := os.Getwd()
+
+	if req.Cwd != "" {
+		if err := os.Chdir(req.Cwd); err != nil {
+			return nil, fmt.Errorf("failed to enter %s: %w", req.Cwd, err)
+		}
+	}
+	if req.Env != nil {
+		setEnv(req.Env)
+	}
+
+	return func() {
+		if req.Env != nil {
+			setEnv(origEnv)
+		}
+		if origCwd != "" {
+			_ = os.Chdir(origCwd)
+		}
+	}, nil
+}
+
+func setEnv(env []string) {
+	os.Clearenv()
+	for _, kv := range env {
+		for i := 0; i < len(kv); i++ {
+			if kv[i] == '=' {
+				_ = os.Setenv(kv[:i], kv[i+1:])
+				break
+			}
+		}
+	}
+}
+
+// frameWriter wraps output in Frames so stdout and stderr share one socket
+type frameWriter struct {
+	conn   net.Conn
+	stream string
+	mu     *sync.Mutex
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := writeFrame(w.conn, Frame{Stream: w.stream, Data: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func writeFrame(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ErrNotRunning is returned by Forward when no daemon answers on the socket
+var ErrNotRunning = errors.New("clanker daemon is not running")
+
+// Running reports whether a daemon accepts connections on socketPath
+func Running(socketPath string) bool {
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// Forward sends req to the daemon and copies its output to stdout/stderr.
+// It returns ErrNotRunning if the daemon cannot be reached, so callers can
+// fall back to running the command locally. A non-nil error otherwise is
+// the remote command's error.
+func Forward(socketPath string, req Request, stdout, stderr io.Writer) error {
+	conn, err := net.DialTimeout("unix", socketPath, dialTimeout)
+	if err != nil {
+		return ErrNotRunning
+	}
+	defer conn.Close()
+
+	if err := writeFrame(conn, req); err != nil {
+		return ErrNotRunning
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var f Frame
+		if err := decoder.Decode(&f); err != nil {
+			return fmt.Errorf("lost connection to clanker daemon: %w", err)
+		}
+		switch f.Stream {
+		case "stdout":
+			_, _ = io.WriteString(stdout, f.Data)
+		case "stderr":
+			_, _ = io.WriteString(stderr, f.Data)
+		}
+		if f.Done {
+			if f.Error != "" {
+				return errors.New(f.Error)
+			}
+			return nil
+		}
+	}
+}
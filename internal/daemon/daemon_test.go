@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shortSocketPath avoids the ~104 byte unix socket path limit that long
+// t.TempDir paths can exceed
+func shortSocketPath(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "ckd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "d.sock")
+}
+
+func startServer(t *testing.T, run RunFunc) (string, <-chan error) {
+	t.Helper()
+	socketPath := shortSocketPath(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	done := make(chan error, 1)
+	go func() { done <- NewServer(socketPath, run, false).Serve(ctx) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !Running(socketPath) {
+		if time.Now().After(deadline) {
+			t.Fatal("daemon did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return socketPath, done
+}
+
+func TestForwardStreamsOutputAndErrors(t *testing.T) {
+	socketPath, _ := startServer(t, func(args []string, stdout, stderr io.Writer) error {
+		cwd, _ := os.Getwd()
+		fmt.Fprintf(stdout, "args=%s cwd=%s env=%s\n", strings.Join(args, ","), cwd, os.Getenv("CLANKER_TEST_VALUE"))
+		fmt.Fprintln(stderr, "warning: partial")
+		if len(args) > 1 && args[1] == "fail" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	cwd := os.TempDir()
+	var stdout, stderr bytes.Buffer
+	err := Forward(socketPath, Request{Args: []string{"ask", "hi"}, Cwd: cwd, Env: []string{"CLANKER_TEST_VALUE=from-client"}}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("Forward: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "args=ask,hi") || !strings.Contains(stdout.String(), "env=from-client") {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	if stderr.String() != "warning: partial\n" {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+	if os.Getenv("CLANKER_TEST_VALUE") != "" {
+		t.Error("request environment leaked into the daemon")
+	}
+
+	err = Forward(socketPath, Request{Args: []string{"ask", "fail"}}, io.Discard, io.Discard)
+	if err == nil || err.Error() != "boom" {
+		t.Errorf("remote error = %v, want boom", err)
+	}
+}
+
+func TestForwardWithoutDaemon(t *testing.T) {
+	err := Forward(shortSocketPath(t), Request{Args: []string{"ask"}}, io.Discard, io.Discard)
+	if !errors.Is(err, ErrNotRunning) {
+		t.Errorf("err = %v, want ErrNotRunning", err)
+	}
+}
+
+func TestShutdownControl(t *testing.T) {
+	socketPath, done := startServer(t, func(args []string, stdout, stderr io.Writer) error { return nil })
+
+	var status bytes.Buffer
+	if err := Forward(socketPath, Request{Control: "ping"}, &status, io.Discard); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if !strings.Contains(status.String(), "commands served") {
+		t.Errorf("status = %q", status.String())
+	}
+
+	if err := Forward(socketPath, Request{Control: "shutdown"}, io.Discard, io.Discard); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Error("socket file not removed on shutdown")
+	}
+}
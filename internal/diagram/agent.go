@@ -0,0 +1,199 @@
+package diagram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/aws/rds"
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/log"
+)
+
+var logger = log.New("diagram")
+
+// Sources read the inventory diagrams are drawn from. A nil source is not
+// configured and is skipped.
+type Sources struct {
+	// Cluster lists the current cluster's resources
+	Cluster func(ctx context.Context, namespace string) (*k8s.ClusterResources, error)
+	// RDS describes the DB instances and clusters in the AWS region
+	RDS func(ctx context.Context) (*rds.Inventory, error)
+}
+
+// Agent answers diagram questions
+type Agent struct {
+	sources Sources
+}
+
+// NewAgent creates a diagram agent reading inventory from sources
+func NewAgent(sources Sources) *Agent {
+	return &Agent{sources: sources}
+}
+
+// Result is a diagram after a question changed or rendered it
+type Result struct {
+	Diagram *Diagram
+	// Rendered is the diagram in the requested format
+	Rendered string
+	// Summary says what changed, and which sources could not be read
+	Summary string
+}
+
+// HandleQuery creates, updates or renders the diagram question names, and
+// saves it when it changed
+func (a *Agent) HandleQuery(ctx context.Context, question string) (*Result, error) {
+	req := ParseRequest(question)
+	logger.Debugf("op=%d format=%s name=%s sources=%v target=%q", req.Op, req.Format, req.Name, req.Sources, req.Target)
+
+	var (
+		d       *Diagram
+		summary string
+		err     error
+	)
+	switch req.Op {
+	case Show:
+		if d, err = Load(req.Name); err != nil {
+			return nil, missingDiagram(req.Name, err)
+		}
+		summary = fmt.Sprintf("Diagram %q: %d resources, %d connections", d.Name, len(d.Nodes), len(d.Edges))
+
+	case Remove:
+		if req.Target == "" {
+			return nil, fmt.Errorf("name the resource to remove, e.g. \"remove orders-db from the diagram\"")
+		}
+		if d, err = Load(req.Name); err != nil {
+			return nil, missingDiagram(req.Name, err)
+		}
+		removed := d.Remove(req.Target)
+		if len(removed) == 0 {
+			return nil, fmt.Errorf("diagram %q has no resource named %s", d.Name, req.Target)
+		}
+		summary = fmt.Sprintf("Removed %s from diagram %q", describe(removed), d.Name)
+
+	case Add:
+		inv, warnings, err := a.inventory(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		d, err = Load(req.Name)
+		if errors.Is(err, os.ErrNotExist) {
+			d, err = &Diagram{Name: req.Name}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if req.Target != "" {
+			if inv = inv.Only(req.Target); len(inv.Nodes) == 0 {
+				return nil, fmt.Errorf("no %s resource named %s found", strings.Join(req.Sources, " or "), req.Target)
+			}
+		}
+		added := d.Merge(inv)
+		if len(added) == 0 {
+			summary = fmt.Sprintf("Diagram %q already has every %s resource", d.Name, strings.Join(req.Sources, " and "))
+		} else {
+			summary = fmt.Sprintf("Added %s to diagram %q", describe(added), d.Name)
+		}
+		summary += warnings
+
+	default:
+		inv, warnings, err := a.inventory(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		d = inv
+		d.Name = req.Name
+		summary = fmt.Sprintf("Drew diagram %q: %d resources, %d connections", d.Name, len(d.Nodes), len(d.Edges)) + warnings
+	}
+
+	if req.Op != Show {
+		d.UpdatedAt = time.Now().UTC()
+		if err := Save(d); err != nil {
+			return nil, err
+		}
+		if path, err := Path(d.Name); err == nil {
+			summary += "\nSaved to " + path
+		}
+	}
+
+	rendered, err := Render(d, req.Format)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Diagram: d, Rendered: rendered, Summary: summary}, nil
+}
+
+// inventory reads each source the request names into one diagram. A source
+// that fails is reported in the returned warnings unless all of them fail.
+func (a *Agent) inventory(ctx context.Context, req Request) (*Diagram, string, error) {
+	d := &Diagram{}
+	var warnings []string
+	var errs []error
+	for _, source := range req.Sources {
+		part, err := a.read(ctx, source, req.Namespace)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+			warnings = append(warnings, fmt.Sprintf("%s skipped: %v", source, err))
+			continue
+		}
+		d.Merge(part)
+	}
+	if len(errs) == len(req.Sources) {
+		return nil, "", fmt.Errorf("no inventory could be read: %w", errors.Join(errs...))
+	}
+	if len(warnings) == 0 {
+		return d, "", nil
+	}
+	return d, "\n" + strings.Join(warnings, "\n"), nil
+}
+
+func (a *Agent) read(ctx context.Context, source, namespace string) (*Diagram, error) {
+	switch source {
+	case SourceKubernetes:
+		if a.sources.Cluster == nil {
+			return nil, fmt.Errorf("no cluster configured")
+		}
+		resources, err := a.sources.Cluster(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		if resources.PodSummary != nil {
+			return nil, fmt.Errorf("%d pods is too many to draw; name a namespace", resources.PodSummary.Total)
+		}
+		return FromCluster(resources, namespace), nil
+	case SourceRDS:
+		if a.sources.RDS == nil {
+			return nil, fmt.Errorf("no AWS profile configured")
+		}
+		inv, err := a.sources.RDS(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return FromRDS(inv), nil
+	}
+	return nil, fmt.Errorf("unknown source %q", source)
+}
+
+// describe names a few nodes for a summary
+func describe(nodes []Node) string {
+	const shown = 5
+	var names []string
+	for i, n := range nodes {
+		if i == shown {
+			names = append(names, fmt.Sprintf("%d more", len(nodes)-shown))
+			break
+		}
+		names = append(names, n.Kind+" "+n.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func missingDiagram(name string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no saved diagram %q yet; ask to draw one first, e.g. \"draw a diagram of my cluster\"", name)
+	}
+	return err
+}
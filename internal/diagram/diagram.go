@@ -0,0 +1,124 @@
+// Package diagram turns cluster and AWS inventory into architecture
+// diagrams and keeps them current. A diagram is saved as JSON under
+// ~/.clanker/diagrams and rendered as JSON, Mermaid or draw.io XML.
+// Questions such as "add the new RDS instance to the diagram" load the
+// saved diagram, read the inventory again and add only what it lacks.
+package diagram
+
+import (
+	"strings"
+	"time"
+)
+
+// Node is one resource on a diagram
+type Node struct {
+	// ID is stable across inventory reads, e.g. aws:rds-instance/orders-db
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Group is the box the node is drawn in, such as a cluster namespace
+	Group string `json:"group,omitempty"`
+}
+
+// Edge connects two nodes by ID
+type Edge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"`
+}
+
+// Diagram is a saved set of nodes and edges
+type Diagram struct {
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Nodes     []Node    `json:"nodes"`
+	Edges     []Edge    `json:"edges"`
+}
+
+// Merge adds the nodes of other that d lacks, and the edges of other whose
+// ends are both on d afterwards. It returns the nodes it added.
+func (d *Diagram) Merge(other *Diagram) []Node {
+	have := d.nodeIDs()
+	var added []Node
+	for _, n := range other.Nodes {
+		if have[n.ID] {
+			continue
+		}
+		have[n.ID] = true
+		d.Nodes = append(d.Nodes, n)
+		added = append(added, n)
+	}
+
+	edges := map[Edge]bool{}
+	for _, e := range d.Edges {
+		edges[e] = true
+	}
+	for _, e := range other.Edges {
+		if have[e.From] && have[e.To] && !edges[e] {
+			edges[e] = true
+			d.Edges = append(d.Edges, e)
+		}
+	}
+	return added
+}
+
+// Remove deletes the nodes named name, matched without case, with their
+// edges. It returns the nodes it removed.
+func (d *Diagram) Remove(name string) []Node {
+	var kept, removed []Node
+	gone := map[string]bool{}
+	for _, n := range d.Nodes {
+		if strings.EqualFold(n.Name, name) {
+			removed = append(removed, n)
+			gone[n.ID] = true
+			continue
+		}
+		kept = append(kept, n)
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	d.Nodes = kept
+
+	edges := d.Edges[:0]
+	for _, e := range d.Edges {
+		if !gone[e.From] && !gone[e.To] {
+			edges = append(edges, e)
+		}
+	}
+	d.Edges = edges
+	return removed
+}
+
+// Only returns the part of d whose nodes are named name, keeping every
+// edge so Merge can connect them to nodes already drawn
+func (d *Diagram) Only(name string) *Diagram {
+	out := &Diagram{Name: d.Name, Edges: d.Edges}
+	for _, n := range d.Nodes {
+		if strings.EqualFold(n.Name, name) {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+	return out
+}
+
+func (d *Diagram) nodeIDs() map[string]bool {
+	ids := make(map[string]bool, len(d.Nodes))
+	for _, n := range d.Nodes {
+		ids[n.ID] = true
+	}
+	return ids
+}
+
+// groups returns the group names in the order their first node appears
+func (d *Diagram) groups() []string {
+	seen := map[string]bool{}
+	var groups []string
+	for _, n := range d.Nodes {
+		if !seen[n.Group] {
+			seen[n.Group] = true
+			groups = append(groups, n.Group)
+		}
+	}
+	return groups
+}
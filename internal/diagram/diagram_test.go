@@ -0,0 +1,152 @@
+package diagram
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/aws/rds"
+)
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     Request
+	}{
+		{"draw a diagram of my cluster and databases", Request{Op: Create, Format: FormatMermaid, Name: DefaultName, Sources: []string{SourceKubernetes, SourceRDS}}},
+		{"add the new RDS instance to the diagram", Request{Op: Add, Format: FormatMermaid, Name: DefaultName, Sources: []string{SourceRDS}}},
+		{"add orders-db to the diagram", Request{Op: Add, Format: FormatMermaid, Name: DefaultName, Sources: []string{SourceKubernetes, SourceRDS}, Target: "orders-db"}},
+		{"remove the orders-db instance from the diagram", Request{Op: Remove, Format: FormatMermaid, Name: DefaultName, Sources: []string{SourceKubernetes, SourceRDS}, Target: "orders-db"}},
+		{"export the diagram as draw.io", Request{Op: Show, Format: FormatDrawIO, Name: DefaultName, Sources: []string{SourceKubernetes, SourceRDS}}},
+		{"draw a diagram named prod of the shop namespace as json", Request{Op: Create, Format: FormatJSON, Name: "prod", Sources: []string{SourceKubernetes}, Namespace: "shop"}},
+		{"diagram the aurora cluster", Request{Op: Create, Format: FormatMermaid, Name: DefaultName, Sources: []string{SourceRDS}}},
+	}
+	for _, tt := range tests {
+		if got := ParseRequest(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestIsDiagramQuestion(t *testing.T) {
+	for q, want := range map[string]bool{
+		"add the new RDS instance to the diagram": true,
+		"export it as drawio":                     true,
+		"list my RDS instances":                   false,
+		"draw down the replica count":             false,
+	} {
+		if got := IsDiagramQuestion(q); got != want {
+			t.Errorf("IsDiagramQuestion(%q) = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func TestMergeAndRemove(t *testing.T) {
+	d := FromRDS(&rds.Inventory{
+		Clusters:  []rds.Cluster{{ID: "orders", Members: []rds.Member{{ID: "orders-1", Writer: true}}}},
+		Instances: []rds.Instance{{ID: "orders-1"}},
+	})
+	fresh := FromRDS(&rds.Inventory{
+		Clusters:  []rds.Cluster{{ID: "orders", Members: []rds.Member{{ID: "orders-1", Writer: true}, {ID: "orders-2"}}}},
+		Instances: []rds.Instance{{ID: "orders-1"}, {ID: "orders-2"}, {ID: "billing"}},
+	})
+
+	added := d.Merge(fresh.Only("orders-2"))
+	if len(added) != 1 || added[0].Name != "orders-2" {
+		t.Fatalf("Merge added %+v, want orders-2", added)
+	}
+	if len(d.Nodes) != 3 || len(d.Edges) != 2 {
+		t.Fatalf("after Merge: %d nodes, %d edges, want 3 and 2", len(d.Nodes), len(d.Edges))
+	}
+	if added := d.Merge(fresh.Only("orders-2")); len(added) != 0 {
+		t.Errorf("second Merge added %+v", added)
+	}
+
+	removed := d.Remove("ORDERS-1")
+	if len(removed) != 1 || len(d.Nodes) != 2 || len(d.Edges) != 1 {
+		t.Errorf("Remove: removed %+v, %d nodes, %d edges", removed, len(d.Nodes), len(d.Edges))
+	}
+	if removed := d.Remove("missing"); removed != nil {
+		t.Errorf("Remove(missing) = %+v", removed)
+	}
+}
+
+func TestRender(t *testing.T) {
+	d := &Diagram{
+		Name: "prod",
+		Nodes: []Node{
+			{ID: "a", Kind: KindRDSCluster, Name: "orders", Group: rdsGroup},
+			{ID: "b", Kind: KindRDSInstance, Name: `orders "1"`, Group: rdsGroup},
+			{ID: "c", Kind: "Pod", Name: "web<1>"},
+		},
+		Edges: []Edge{{From: "a", To: "b", Label: "writer"}},
+	}
+
+	mermaid := Mermaid(d)
+	for _, want := range []string{"flowchart LR", `subgraph g0["AWS RDS"]`, `n1["RDS instance<br/>orders #quot;1#quot;"]`, "n0 -->|writer| n1", `  n2["Pod<br/>web<1>"]`} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid missing %q:\n%s", want, mermaid)
+		}
+	}
+
+	drawio := DrawIO(d)
+	for _, want := range []string{`<mxfile host="clanker">`, `value="AWS RDS" style="swimlane`, `parent="g0"`, `value="Pod&lt;br&gt;web&lt;1&gt;"`, `source="n0" target="n1"`} {
+		if !strings.Contains(drawio, want) {
+			t.Errorf("DrawIO missing %q:\n%s", want, drawio)
+		}
+	}
+
+	if _, err := Render(d, "svg"); err == nil {
+		t.Error("Render(svg) should fail")
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := Load("prod"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Load before Save = %v, want os.ErrNotExist", err)
+	}
+	d := &Diagram{Name: "prod", Nodes: []Node{{ID: "a", Kind: "Pod", Name: "web"}}}
+	if err := Save(d); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Nodes, d.Nodes) {
+		t.Errorf("Load = %+v, want %+v", got.Nodes, d.Nodes)
+	}
+}
+
+func TestAgentAddsNewRDSInstance(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	inv := &rds.Inventory{Instances: []rds.Instance{{ID: "orders-db"}}}
+	agent := NewAgent(Sources{RDS: func(context.Context) (*rds.Inventory, error) { return inv, nil }})
+	ctx := context.Background()
+
+	if _, err := agent.HandleQuery(ctx, "draw a diagram of my RDS databases"); err != nil {
+		t.Fatal(err)
+	}
+
+	inv.Instances = append(inv.Instances, rds.Instance{ID: "billing-db"})
+	result, err := agent.HandleQuery(ctx, "add the new RDS instance to the diagram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result.Summary, "Added RDS instance billing-db") {
+		t.Errorf("Summary = %q", result.Summary)
+	}
+	if len(result.Diagram.Nodes) != 2 || !strings.Contains(result.Rendered, "billing-db") {
+		t.Errorf("Diagram = %+v\n%s", result.Diagram, result.Rendered)
+	}
+
+	if _, err := agent.HandleQuery(ctx, "draw a diagram of my pods"); err == nil {
+		t.Error("a diagram of an unconfigured cluster should fail")
+	}
+}
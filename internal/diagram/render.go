@@ -0,0 +1,130 @@
+package diagram
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Formats a diagram renders to
+const (
+	FormatJSON    = "json"
+	FormatMermaid = "mermaid"
+	FormatDrawIO  = "drawio"
+)
+
+// Render returns d in format
+func Render(d *Diagram, format string) (string, error) {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal diagram: %w", err)
+		}
+		return string(data) + "\n", nil
+	case FormatMermaid:
+		return Mermaid(d), nil
+	case FormatDrawIO:
+		return DrawIO(d), nil
+	}
+	return "", fmt.Errorf("unsupported diagram format %q (use json, mermaid or drawio)", format)
+}
+
+// Mermaid renders d as a flowchart with a subgraph per group
+func Mermaid(d *Diagram) string {
+	ids := positions(d)
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for gi, group := range d.groups() {
+		indent := "  "
+		if group != "" {
+			fmt.Fprintf(&b, "  subgraph g%d[\"%s\"]\n", gi, mermaidText(group))
+			indent = "    "
+		}
+		for _, n := range d.Nodes {
+			if n.Group == group {
+				fmt.Fprintf(&b, "%sn%d[\"%s<br/>%s\"]\n", indent, ids[n.ID], mermaidText(n.Kind), mermaidText(n.Name))
+			}
+		}
+		if group != "" {
+			b.WriteString("  end\n")
+		}
+	}
+	for _, e := range d.Edges {
+		if e.Label != "" {
+			fmt.Fprintf(&b, "  n%d -->|%s| n%d\n", ids[e.From], mermaidText(e.Label), ids[e.To])
+			continue
+		}
+		fmt.Fprintf(&b, "  n%d --> n%d\n", ids[e.From], ids[e.To])
+	}
+	return b.String()
+}
+
+// draw.io layout: each group is a column of nodes
+const (
+	drawioNodeWidth  = 180
+	drawioNodeHeight = 50
+	drawioGap        = 30
+	drawioHeader     = 40
+)
+
+// DrawIO renders d as a draw.io (diagrams.net) file. Each group becomes a
+// container with its nodes stacked inside, so the file opens laid out.
+func DrawIO(d *Diagram) string {
+	ids := positions(d)
+	var b strings.Builder
+	fmt.Fprintf(&b, "<mxfile host=\"clanker\">\n  <diagram name=\"%s\" id=\"clanker\">\n", xmlText(d.Name))
+	b.WriteString("    <mxGraphModel grid=\"1\" gridSize=\"10\" guides=\"1\" arrows=\"1\" connect=\"1\" page=\"0\">\n      <root>\n")
+	b.WriteString("        <mxCell id=\"0\"/>\n        <mxCell id=\"1\" parent=\"0\"/>\n")
+
+	groupWidth := drawioNodeWidth + 2*drawioGap
+	for gi, group := range d.groups() {
+		var members []Node
+		for _, n := range d.Nodes {
+			if n.Group == group {
+				members = append(members, n)
+			}
+		}
+		parent := "1"
+		x, y := gi*(groupWidth+drawioGap), 0
+		if group != "" {
+			parent = fmt.Sprintf("g%d", gi)
+			height := drawioHeader + len(members)*(drawioNodeHeight+drawioGap)
+			fmt.Fprintf(&b, "        <mxCell id=\"%s\" value=\"%s\" style=\"swimlane;rounded=1;whiteSpace=wrap;html=1;\" vertex=\"1\" parent=\"1\">\n", parent, xmlText(group))
+			fmt.Fprintf(&b, "          <mxGeometry x=\"%d\" y=\"0\" width=\"%d\" height=\"%d\" as=\"geometry\"/>\n        </mxCell>\n", x, groupWidth, height)
+			x, y = drawioGap, drawioHeader
+		}
+		for i, n := range members {
+			fmt.Fprintf(&b, "        <mxCell id=\"n%d\" value=\"%s\" style=\"rounded=1;whiteSpace=wrap;html=1;\" vertex=\"1\" parent=\"%s\">\n", ids[n.ID], xmlText(n.Kind+"<br>"+n.Name), parent)
+			fmt.Fprintf(&b, "          <mxGeometry x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" as=\"geometry\"/>\n        </mxCell>\n", x, y+i*(drawioNodeHeight+drawioGap), drawioNodeWidth, drawioNodeHeight)
+		}
+	}
+	for i, e := range d.Edges {
+		fmt.Fprintf(&b, "        <mxCell id=\"e%d\" value=\"%s\" style=\"edgeStyle=orthogonalEdgeStyle;html=1;\" edge=\"1\" parent=\"1\" source=\"n%d\" target=\"n%d\">\n", i, xmlText(e.Label), ids[e.From], ids[e.To])
+		b.WriteString("          <mxGeometry relative=\"1\" as=\"geometry\"/>\n        </mxCell>\n")
+	}
+	b.WriteString("      </root>\n    </mxGraphModel>\n  </diagram>\n</mxfile>\n")
+	return b.String()
+}
+
+// positions numbers the nodes; resource names can hold characters that
+// neither Mermaid nor draw.io allow in an ID
+func positions(d *Diagram) map[string]int {
+	ids := make(map[string]int, len(d.Nodes))
+	for i, n := range d.Nodes {
+		ids[n.ID] = i
+	}
+	return ids
+}
+
+func mermaidText(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, `"`, "#quot;"), "|", "#124;")
+}
+
+func xmlText(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
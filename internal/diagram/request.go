@@ -0,0 +1,146 @@
+package diagram
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Operation is what a question asks of a diagram
+type Operation int
+
+const (
+	// Create draws a diagram from the inventory, replacing a saved one
+	Create Operation = iota + 1
+	// Add puts resources the saved diagram lacks on it
+	Add
+	// Remove takes a named resource off the saved diagram
+	Remove
+	// Show renders the saved diagram without reading inventory
+	Show
+)
+
+// Inventory sources a diagram is drawn from
+const (
+	SourceKubernetes = "kubernetes"
+	SourceRDS        = "rds"
+)
+
+// Request is a question translated into a diagram operation
+type Request struct {
+	Op     Operation
+	Format string
+	// Name is the saved diagram; DefaultName when the question names none
+	Name    string
+	Sources []string
+	// Target is the resource to add or remove; empty adds everything the
+	// diagram lacks
+	Target    string
+	Namespace string
+}
+
+var (
+	diagramRe  = regexp.MustCompile(`(?i)\b(?:diagrams?|draw\.?io|diagrams\.net|mermaid)\b`)
+	addRe      = regexp.MustCompile(`(?i)\b(?:add|include|put|append|update|refresh|sync)\b`)
+	removeRe   = regexp.MustCompile(`(?i)\b(?:remove|delete|drop|take\s+(?:out|off))\b`)
+	showRe     = regexp.MustCompile(`(?i)\b(?:show|print|render|export|open|view|display|convert)\s+(?:me\s+)?(?:the|my|saved|current|existing)\b`)
+	createRe   = regexp.MustCompile(`(?i)\b(?:create|draw|generate|make|build)\b`)
+	drawioRe   = regexp.MustCompile(`(?i)\b(?:draw\.?io|diagrams\.net|mxgraph|xml)\b`)
+	mermaidRe  = regexp.MustCompile(`(?i)\bmermaid\b`)
+	jsonRe     = regexp.MustCompile(`(?i)\bjson\b`)
+	kubeRe     = regexp.MustCompile(`(?i)\b(?:k8s|kubernetes|pods?|namespaces?|ingress(?:es)?|workloads?|deployments?|services?)\b`)
+	clusterRe  = regexp.MustCompile(`(?i)\bclusters?\b`)
+	dbCluster  = regexp.MustCompile(`(?i)\b(?:aurora|rds|db|database)\s+clusters?\b`)
+	rdsRe      = regexp.MustCompile(`(?i)\b(?:rds|aurora|databases?|db|aws)\b`)
+	nameRe     = regexp.MustCompile(`(?i)\bdiagram\s+(?:named|called)\s+["'` + "`" + `]?([a-z0-9][a-z0-9_.-]*)`)
+	nsBeforeRe = regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9-]*)\s+namespace\b`)
+	nsAfterRe  = regexp.MustCompile(`(?i)\b(?:namespace|ns)\s+["'` + "`" + `]?([a-z0-9][a-z0-9-]*)`)
+	targetRe   = regexp.MustCompile(`(?i)\b(?:add|include|put|remove|delete|drop|take\s+(?:out|off))\s+(.+?)\s+(?:to|from|on|onto|off|in|into)\s+(?:the\s+|my\s+)?(?:saved\s+|current\s+)?diagram\b`)
+	targetWord = regexp.MustCompile(`(?i)["'` + "`" + `]?([a-z0-9][a-z0-9_.-]*)`)
+)
+
+// notTargets are words in "add the new RDS instance to the diagram" that
+// describe rather than name what to add
+var notTargets = map[string]bool{
+	"the": true, "a": true, "an": true, "my": true, "our": true, "new": true, "newest": true,
+	"latest": true, "all": true, "any": true, "missing": true, "rds": true, "aurora": true,
+	"db": true, "database": true, "databases": true, "instance": true, "instances": true,
+	"cluster": true, "clusters": true, "pod": true, "pods": true, "service": true, "services": true,
+	"ingress": true, "k8s": true, "kubernetes": true, "aws": true, "resources": true, "resource": true,
+	"it": true, "them": true, "those": true, "these": true, "that": true, "this": true,
+}
+
+// nsStopwords read as a namespace in "the namespace" or "namespace as json"
+var nsStopwords = map[string]bool{
+	"the": true, "a": true, "my": true, "each": true, "every": true, "one": true, "that": true,
+	"this": true, "as": true, "to": true, "in": true, "of": true, "and": true, "or": true,
+	"for": true, "with": true, "into": true, "from": true,
+}
+
+// IsDiagramQuestion reports whether question asks for a diagram
+func IsDiagramQuestion(question string) bool {
+	return diagramRe.MatchString(question)
+}
+
+// ParseRequest reads a diagram operation from question
+func ParseRequest(question string) Request {
+	req := Request{Op: Create, Format: FormatMermaid, Name: DefaultName}
+
+	switch {
+	case drawioRe.MatchString(question):
+		req.Format = FormatDrawIO
+	case mermaidRe.MatchString(question):
+		req.Format = FormatMermaid
+	case jsonRe.MatchString(question):
+		req.Format = FormatJSON
+	}
+
+	switch {
+	case removeRe.MatchString(question):
+		req.Op = Remove
+	case addRe.MatchString(question):
+		req.Op = Add
+	// The "draw" in draw.io is a format, not a request to draw again
+	case showRe.MatchString(question) && !createRe.MatchString(drawioRe.ReplaceAllString(question, "")):
+		req.Op = Show
+	}
+
+	if m := nameRe.FindStringSubmatch(question); m != nil {
+		req.Name = m[1]
+	}
+	for _, re := range []*regexp.Regexp{nsBeforeRe, nsAfterRe} {
+		if m := re.FindStringSubmatch(question); m != nil && !nsStopwords[strings.ToLower(m[1])] {
+			req.Namespace = strings.ToLower(m[1])
+			break
+		}
+	}
+	if req.Op == Add || req.Op == Remove {
+		if m := targetRe.FindStringSubmatch(question); m != nil {
+			for _, w := range targetWord.FindAllStringSubmatch(m[1], -1) {
+				if !notTargets[strings.ToLower(w[1])] {
+					req.Target = w[1]
+					break
+				}
+			}
+		}
+	}
+
+	// The target's name says nothing about where it runs: orders-db may be
+	// an RDS instance or a pod
+	rest := question
+	if req.Target != "" {
+		rest = strings.ReplaceAll(rest, req.Target, "")
+	}
+	rds := rdsRe.MatchString(rest)
+	// "Aurora cluster" is a database; a cluster on its own is Kubernetes
+	kube := kubeRe.MatchString(rest) || clusterRe.MatchString(dbCluster.ReplaceAllString(rest, "")) || req.Namespace != ""
+	if kube {
+		req.Sources = append(req.Sources, SourceKubernetes)
+	}
+	if rds {
+		req.Sources = append(req.Sources, SourceRDS)
+	}
+	if len(req.Sources) == 0 {
+		req.Sources = []string{SourceKubernetes, SourceRDS}
+	}
+	return req
+}
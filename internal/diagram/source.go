@@ -0,0 +1,63 @@
+package diagram
+
+import (
+	"github.com/bgdnvk/clanker/internal/aws/rds"
+	"github.com/bgdnvk/clanker/internal/k8s"
+)
+
+// Node kinds for RDS resources; Kubernetes nodes keep the kinds of
+// k8s.BuildGraph
+const (
+	KindRDSInstance = "RDS instance"
+	KindRDSCluster  = "RDS cluster"
+)
+
+// rdsGroup is the group RDS nodes are drawn in
+const rdsGroup = "AWS RDS"
+
+// FromCluster draws a cluster's workload graph, limited to namespace when
+// it is set. Nodes are grouped by namespace; cluster-scoped ones such as
+// Nodes and PersistentVolumes share a group for the cluster.
+func FromCluster(resources *k8s.ClusterResources, namespace string) *Diagram {
+	g := k8s.BuildGraph(resources, namespace)
+	prefix := "k8s:" + resources.ClusterName + ":"
+	cluster := "Kubernetes " + resources.ClusterName
+
+	d := &Diagram{}
+	for _, n := range g.Nodes {
+		group := cluster
+		if n.Namespace != "" {
+			group = cluster + " / " + n.Namespace
+		}
+		d.Nodes = append(d.Nodes, Node{ID: prefix + n.ID, Kind: n.Kind, Name: n.Name, Group: group})
+	}
+	for _, e := range g.Edges {
+		d.Edges = append(d.Edges, Edge{From: prefix + e.From, To: prefix + e.To})
+	}
+	return d
+}
+
+// FromRDS draws every DB cluster and instance, with an edge from each
+// cluster to its writer and readers
+func FromRDS(inv *rds.Inventory) *Diagram {
+	d := &Diagram{}
+	for _, c := range inv.Clusters {
+		d.Nodes = append(d.Nodes, Node{ID: rdsClusterID(c.ID), Kind: KindRDSCluster, Name: c.ID, Group: rdsGroup})
+	}
+	for _, i := range inv.Instances {
+		d.Nodes = append(d.Nodes, Node{ID: rdsInstanceID(i.ID), Kind: KindRDSInstance, Name: i.ID, Group: rdsGroup})
+	}
+	for _, c := range inv.Clusters {
+		for _, m := range c.Members {
+			label := "reader"
+			if m.Writer {
+				label = "writer"
+			}
+			d.Edges = append(d.Edges, Edge{From: rdsClusterID(c.ID), To: rdsInstanceID(m.ID), Label: label})
+		}
+	}
+	return d
+}
+
+func rdsClusterID(id string) string  { return "aws:rds-cluster/" + id }
+func rdsInstanceID(id string) string { return "aws:rds-instance/" + id }
@@ -0,0 +1,72 @@
+package diagram
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+)
+
+// DefaultName is the diagram questions update when they name none
+const DefaultName = "default"
+
+// Dir returns ~/.clanker/diagrams
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "diagrams"), nil
+}
+
+// Path returns where the diagram called name is stored
+func Path(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, secfile.SafeSlug(name)+".json"), nil
+}
+
+// Load reads the diagram called name. The error wraps os.ErrNotExist when
+// there is none.
+func Load(name string) (*Diagram, error) {
+	path, err := Path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := secfile.ReadPrivate(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read diagram %q: %w", name, err)
+	}
+	var d Diagram
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse diagram %q: %w", name, err)
+	}
+	return &d, nil
+}
+
+// Save writes d to ~/.clanker/diagrams/<name>.json
+func Save(d *Diagram) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := secfile.EnsurePrivateDir(dir); err != nil {
+		return fmt.Errorf("failed to create diagrams directory: %w", err)
+	}
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal diagram: %w", err)
+	}
+	path, err := Path(d.Name)
+	if err != nil {
+		return err
+	}
+	if err := secfile.WritePrivate(path, data); err != nil {
+		return fmt.Errorf("failed to write diagram: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,447 @@
+// Package dnstruth answers where a name actually resolves and who manages
+// it. It resolves the name live, finds the zones in Route53 and Cloudflare
+// that could hold it, works out which of them the domain is delegated to,
+// and flags records that conflict between zones or point at targets that no
+// longer exist. Dangling records in a serving zone can be turned into plans
+// that delete them.
+package dnstruth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	awsclient "github.com/bgdnvk/clanker/internal/aws"
+	cfdns "github.com/bgdnvk/clanker/internal/cloudflare/dns"
+)
+
+// Resolver looks names up in live DNS; *net.Resolver satisfies it
+type Resolver interface {
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Agent cross-references live DNS with Route53 and Cloudflare
+type Agent struct {
+	aws        awsclient.Runner
+	cloudflare cfdns.CloudflareClient
+	resolver   Resolver
+	debug      bool
+}
+
+// NewAgent creates an agent that reads Route53 through aws and Cloudflare
+// through cloudflare. Either may be nil to leave that provider out.
+func NewAgent(aws awsclient.Runner, cloudflare cfdns.CloudflareClient, resolver Resolver, debug bool) *Agent {
+	return &Agent{aws: aws, cloudflare: cloudflare, resolver: resolver, debug: debug}
+}
+
+// Provider is who serves a zone
+type Provider string
+
+const (
+	Route53    Provider = "route53"
+	Cloudflare Provider = "cloudflare"
+)
+
+// Label is the provider's name in reports
+func (p Provider) Label() string {
+	switch p {
+	case Route53:
+		return "Route53"
+	case Cloudflare:
+		return "Cloudflare"
+	case "":
+		return "unknown"
+	}
+	return string(p)
+}
+
+// Zone is a Route53 hosted zone or Cloudflare zone that could hold the name
+type Zone struct {
+	Provider    Provider
+	Name        string
+	ID          string
+	NameServers []string
+	// Private marks a Route53 private hosted zone, which is never served
+	// publicly
+	Private bool
+	// Serving reports whether the live delegation points at this zone's
+	// nameservers
+	Serving bool
+}
+
+// Record is an address or alias record in one of the zones
+type Record struct {
+	Provider Provider
+	Zone     string
+	ZoneID   string
+	Name     string
+	Type     string
+	Values   []string
+	TTL      int
+	// Alias marks a Route53 alias record, whose value is its target
+	Alias bool
+	// Proxied marks a Cloudflare record served through Cloudflare's
+	// proxy, so live answers are Cloudflare's addresses
+	Proxied bool
+	// ID is the Cloudflare record ID
+	ID string
+	// Serving reports whether the record is in a serving zone
+	Serving bool
+	// raw is the Route53 record set as listed, which a delete has to
+	// repeat exactly
+	raw json.RawMessage
+}
+
+// Target is where a CNAME or alias record points, or "" for an address
+// record
+func (r Record) Target() string {
+	if r.Type == "CNAME" || r.Alias {
+		if len(r.Values) > 0 {
+			return r.Values[0]
+		}
+	}
+	return ""
+}
+
+// Answer is what live DNS returns for the name
+type Answer struct {
+	// CNAME is the canonical name the name resolves through, or "" when
+	// it has no CNAME
+	CNAME     string
+	Addresses []string
+	Err       error
+}
+
+// Report is what the agent found for a name, or for every record of a zone
+// when it was asked to scan one
+type Report struct {
+	Name string
+	// Scan reports whether every record of the zone was checked rather
+	// than just Name
+	Scan bool
+	// DelegatedZone is where the live NS records were found, and
+	// NameServers the nameservers they delegate to
+	DelegatedZone string
+	NameServers   []string
+	// Authority is the provider the nameservers belong to, or the
+	// nameservers' domain when it is neither
+	Authority Provider
+	Answer    Answer
+	Zones     []Zone
+	Records   []Record
+	Findings  []Finding
+	// Errors holds the providers that could not be read
+	Errors map[Provider]error
+}
+
+// Check resolves req.Name live and cross-references it with the Route53 and
+// Cloudflare zones that could hold it
+func (a *Agent) Check(ctx context.Context, req Request) *Report {
+	name := strings.ToLower(strings.TrimSuffix(req.Name, "."))
+	report := &Report{Name: name, Errors: map[Provider]error{}}
+
+	for _, candidate := range candidates(name) {
+		ns, err := a.resolver.LookupNS(ctx, candidate)
+		if err != nil || len(ns) == 0 {
+			continue
+		}
+		report.DelegatedZone = candidate
+		for _, n := range ns {
+			report.NameServers = append(report.NameServers, normalize(n.Host))
+		}
+		sort.Strings(report.NameServers)
+		break
+	}
+	report.Authority = authority(report.NameServers)
+
+	if a.aws != nil {
+		zones, records, err := a.route53(ctx, name)
+		if err != nil {
+			report.Errors[Route53] = err
+		}
+		report.Zones = append(report.Zones, zones...)
+		report.Records = append(report.Records, records...)
+	}
+	if a.cloudflare != nil {
+		zones, records, err := a.cloudflareZones(ctx, name)
+		if err != nil {
+			report.Errors[Cloudflare] = err
+		}
+		report.Zones = append(report.Zones, zones...)
+		report.Records = append(report.Records, records...)
+	}
+
+	// Scanning applies to a zone apex; any other name is checked alone
+	for _, z := range report.Zones {
+		if req.Scan && z.Name == name {
+			report.Scan = true
+		}
+	}
+	for i := range report.Zones {
+		report.Zones[i].Serving = !report.Zones[i].Private && report.Zones[i].Name == report.DelegatedZone && overlaps(report.Zones[i].NameServers, report.NameServers)
+	}
+	serving := map[string]bool{}
+	for _, z := range report.Zones {
+		serving[string(z.Provider)+" "+z.ID] = z.Serving
+	}
+	var records []Record
+	for _, r := range report.Records {
+		if !report.Scan && r.Name != name {
+			continue
+		}
+		r.Serving = serving[string(r.Provider)+" "+r.ZoneID]
+		records = append(records, r)
+	}
+	report.Records = records
+	sortRecords(report.Records)
+
+	if !report.Scan {
+		report.Answer = a.answer(ctx, name)
+	}
+	report.Findings = a.findings(ctx, report)
+	if a.debug {
+		fmt.Printf("[dnstruth] %s: delegated at %q to %v, %d zones, %d records, %d findings\n",
+			name, report.DelegatedZone, report.NameServers, len(report.Zones), len(report.Records), len(report.Findings))
+	}
+	return report
+}
+
+// answer resolves name live
+func (a *Agent) answer(ctx context.Context, name string) Answer {
+	var ans Answer
+	if cname, err := a.resolver.LookupCNAME(ctx, name); err == nil && normalize(cname) != name {
+		ans.CNAME = normalize(cname)
+	}
+	ans.Addresses, ans.Err = a.resolver.LookupHost(ctx, name)
+	sort.Strings(ans.Addresses)
+	return ans
+}
+
+// route53 returns the public and private hosted zones whose name is name or
+// one of its parents, and the address and alias records in them
+func (a *Agent) route53(ctx context.Context, name string) ([]Zone, []Record, error) {
+	var list struct {
+		HostedZones []struct {
+			Id     string
+			Name   string
+			Config struct {
+				PrivateZone bool
+			}
+		}
+	}
+	if err := runJSON(ctx, a.aws, &list, "route53", "list-hosted-zones", "--output", "json"); err != nil {
+		return nil, nil, err
+	}
+	var zones []Zone
+	var records []Record
+	for _, hz := range list.HostedZones {
+		zoneName := normalize(hz.Name)
+		if !inZone(name, zoneName) {
+			continue
+		}
+		zone := Zone{Provider: Route53, Name: zoneName, ID: strings.TrimPrefix(hz.Id, "/hostedzone/"), Private: hz.Config.PrivateZone}
+		if !zone.Private {
+			var detail struct {
+				DelegationSet struct {
+					NameServers []string
+				}
+			}
+			if err := runJSON(ctx, a.aws, &detail, "route53", "get-hosted-zone", "--id", zone.ID, "--output", "json"); err != nil {
+				return zones, records, err
+			}
+			for _, ns := range detail.DelegationSet.NameServers {
+				zone.NameServers = append(zone.NameServers, normalize(ns))
+			}
+			sort.Strings(zone.NameServers)
+		}
+		zones = append(zones, zone)
+
+		var sets struct {
+			ResourceRecordSets []json.RawMessage
+		}
+		if err := runJSON(ctx, a.aws, &sets, "route53", "list-resource-record-sets", "--hosted-zone-id", zone.ID, "--output", "json"); err != nil {
+			return zones, records, err
+		}
+		for _, raw := range sets.ResourceRecordSets {
+			var set struct {
+				Name            string
+				Type            string
+				TTL             int
+				ResourceRecords []struct {
+					Value string
+				}
+				AliasTarget *struct {
+					DNSName string
+				}
+			}
+			if err := json.Unmarshal(raw, &set); err != nil {
+				return zones, records, fmt.Errorf("failed to parse record set: %w", err)
+			}
+			if !addressType(set.Type) {
+				continue
+			}
+			r := Record{Provider: Route53, Zone: zoneName, ZoneID: zone.ID, Name: normalize(set.Name), Type: set.Type, TTL: set.TTL, raw: raw}
+			if set.AliasTarget != nil {
+				r.Alias = true
+				r.Values = []string{normalize(set.AliasTarget.DNSName)}
+			}
+			for _, rr := range set.ResourceRecords {
+				r.Values = append(r.Values, normalize(rr.Value))
+			}
+			records = append(records, r)
+		}
+	}
+	return zones, records, nil
+}
+
+// cloudflareZones returns the Cloudflare zones whose name is name or one
+// of its parents, and the address records in them
+func (a *Agent) cloudflareZones(ctx context.Context, name string) ([]Zone, []Record, error) {
+	sub := cfdns.NewSubAgent(a.cloudflare, a.debug)
+	var zones []Zone
+	var records []Record
+	for _, candidate := range candidates(name) {
+		raw, err := a.cloudflare.RunAPIWithContext(ctx, "GET", "/zones?name="+candidate, "")
+		if err != nil {
+			return zones, records, err
+		}
+		var resp struct {
+			Result []cfdns.Zone `json:"result"`
+		}
+		if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+			return zones, records, fmt.Errorf("failed to parse zone response: %w", err)
+		}
+		for _, z := range resp.Result {
+			zone := Zone{Provider: Cloudflare, Name: normalize(z.Name), ID: z.ID}
+			for _, ns := range z.NameServers {
+				zone.NameServers = append(zone.NameServers, normalize(ns))
+			}
+			sort.Strings(zone.NameServers)
+			zones = append(zones, zone)
+
+			_, cfRecords, err := sub.ZoneRecords(ctx, z.Name)
+			if err != nil {
+				return zones, records, err
+			}
+			for _, rec := range cfRecords {
+				if !addressType(rec.Type) {
+					continue
+				}
+				records = append(records, Record{
+					Provider: Cloudflare,
+					Zone:     zone.Name,
+					ZoneID:   z.ID,
+					Name:     normalize(rec.Name),
+					Type:     rec.Type,
+					Values:   []string{normalize(rec.Content)},
+					TTL:      rec.TTL,
+					Proxied:  rec.Proxied,
+					ID:       rec.ID,
+				})
+			}
+		}
+	}
+	return zones, records, nil
+}
+
+// resolves reports whether target has an address; only a definite "no
+// such host" counts as not resolving, so a flaky resolver flags nothing
+func (a *Agent) resolves(ctx context.Context, target string) bool {
+	_, err := a.resolver.LookupHost(ctx, target)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	return true
+}
+
+func runJSON(ctx context.Context, run awsclient.Runner, out any, args ...string) error {
+	raw, err := run(ctx, args)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("failed to parse %s %s output: %w", args[0], args[1], err)
+	}
+	return nil
+}
+
+// candidates are name and each parent that could be a zone, longest first
+func candidates(name string) []string {
+	labels := strings.Split(name, ".")
+	var out []string
+	for i := 0; i+2 <= len(labels); i++ {
+		out = append(out, strings.Join(labels[i:], "."))
+	}
+	return out
+}
+
+// authority names the provider that nameservers belong to
+func authority(nameServers []string) Provider {
+	if len(nameServers) == 0 {
+		return ""
+	}
+	ns := nameServers[0]
+	switch {
+	case strings.HasSuffix(ns, ".ns.cloudflare.com"):
+		return Cloudflare
+	case strings.Contains(ns, ".awsdns-"):
+		return Route53
+	}
+	// Another DNS host; name it by the nameserver's own domain
+	labels := strings.Split(ns, ".")
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return Provider(strings.Join(labels, "."))
+}
+
+// normalize lowercases a DNS name and drops its trailing dot and Route53's
+// escaped wildcard
+func normalize(name string) string {
+	name = strings.ReplaceAll(name, `\052`, "*")
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+func inZone(name, zone string) bool {
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+func addressType(t string) bool {
+	return t == "A" || t == "AAAA" || t == "CNAME"
+}
+
+func overlaps(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortRecords orders records by name, then serving zones first, then
+// provider and type
+func sortRecords(records []Record) {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Serving != b.Serving {
+			return a.Serving
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Type < b.Type
+	})
+}
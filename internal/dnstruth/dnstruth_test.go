@@ -0,0 +1,267 @@
+package dnstruth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	awsclient "github.com/bgdnvk/clanker/internal/aws"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// fakeResolver answers from maps; names it does not know do not exist
+type fakeResolver struct {
+	ns    map[string][]string
+	cname map[string]string
+	hosts map[string][]string
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (f fakeResolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	hosts, ok := f.ns[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+	var out []*net.NS
+	for _, h := range hosts {
+		out = append(out, &net.NS{Host: h})
+	}
+	return out, nil
+}
+
+func (f fakeResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	if c, ok := f.cname[host]; ok {
+		return c, nil
+	}
+	return host + ".", nil
+}
+
+func (f fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	addrs, ok := f.hosts[host]
+	if !ok {
+		return nil, notFound(host)
+	}
+	return addrs, nil
+}
+
+// fakeCloudflare answers API calls by method and endpoint
+type fakeCloudflare map[string]string
+
+func (f fakeCloudflare) RunAPI(method, endpoint, body string) (string, error) {
+	return f.RunAPIWithContext(context.Background(), method, endpoint, body)
+}
+
+func (f fakeCloudflare) RunAPIWithContext(ctx context.Context, method, endpoint, body string) (string, error) {
+	if out, ok := f[method+" "+endpoint]; ok {
+		return out, nil
+	}
+	return `{"success": true, "result": []}`, nil
+}
+
+func (f fakeCloudflare) GetAccountID() string { return "acct" }
+
+// fakeAWS answers aws commands by their first two args
+func fakeAWS(outputs map[string]string) awsclient.Runner {
+	return func(ctx context.Context, args []string) (string, error) {
+		if out, ok := outputs[args[0]+" "+args[1]]; ok {
+			return out, nil
+		}
+		return "", errors.New("unexpected call: " + strings.Join(args, " "))
+	}
+}
+
+const (
+	hostedZonesOutput = `{"HostedZones": [
+		{"Id": "/hostedzone/Z1", "Name": "example.com.", "Config": {"PrivateZone": false}},
+		{"Id": "/hostedzone/Z9", "Name": "other.org.", "Config": {"PrivateZone": false}}
+	]}`
+	hostedZoneOutput = `{"DelegationSet": {"NameServers": ["ns-1.awsdns-01.org", "ns-2.awsdns-02.com"]}}`
+	recordSetsOutput = `{"ResourceRecordSets": [
+		{"Name": "example.com.", "Type": "NS", "TTL": 172800, "ResourceRecords": [{"Value": "ns-1.awsdns-01.org."}]},
+		{"Name": "api.example.com.", "Type": "A", "TTL": 300, "ResourceRecords": [{"Value": "192.0.2.1"}]},
+		{"Name": "old.example.com.", "Type": "A", "AliasTarget": {"HostedZoneId": "Z35SXDOTRQ7X7K", "DNSName": "dualstack.gone-123.us-east-1.elb.amazonaws.com.", "EvaluateTargetHealth": false}}
+	]}`
+)
+
+func testAgent() *Agent {
+	return NewAgent(
+		fakeAWS(map[string]string{
+			"route53 list-hosted-zones":         hostedZonesOutput,
+			"route53 get-hosted-zone":           hostedZoneOutput,
+			"route53 list-resource-record-sets": recordSetsOutput,
+		}),
+		fakeCloudflare{
+			"GET /zones?name=example.com": `{"success": true, "result": [{"id": "z1", "name": "example.com",
+				"name_servers": ["ada.ns.cloudflare.com", "bob.ns.cloudflare.com"]}]}`,
+			"GET /zones/z1/dns_records?per_page=500&page=1": `{"success": true, "result_info": {"total_pages": 1}, "result": [
+				{"id": "r1", "type": "CNAME", "name": "api.example.com", "content": "shop.example.net", "ttl": 1},
+				{"id": "r2", "type": "CNAME", "name": "legacy.example.com", "content": "missing.herokuapp.com", "ttl": 1},
+				{"id": "r3", "type": "TXT", "name": "example.com", "content": "v=spf1 -all", "ttl": 1}
+			]}`,
+		},
+		fakeResolver{
+			ns:    map[string][]string{"example.com": {"bob.ns.cloudflare.com.", "ada.ns.cloudflare.com."}},
+			cname: map[string]string{"api.example.com": "shop.example.net."},
+			hosts: map[string][]string{"api.example.com": {"203.0.113.10"}, "shop.example.net": {"203.0.113.10"}},
+		},
+		false)
+}
+
+func kinds(findings []Finding) []FindingKind {
+	var out []FindingKind
+	for _, f := range findings {
+		out = append(out, f.Kind)
+	}
+	return out
+}
+
+func TestCheckName(t *testing.T) {
+	report := testAgent().Check(context.Background(), ParseRequest("where does api.example.com actually resolve and who manages it"))
+	if len(report.Errors) != 0 {
+		t.Fatalf("errors = %v", report.Errors)
+	}
+	if report.DelegatedZone != "example.com" || report.Authority != Cloudflare {
+		t.Errorf("delegation = %q %q", report.DelegatedZone, report.Authority)
+	}
+	if report.Answer.CNAME != "shop.example.net" || !reflect.DeepEqual(report.Answer.Addresses, []string{"203.0.113.10"}) {
+		t.Errorf("answer = %+v", report.Answer)
+	}
+	if len(report.Zones) != 2 || report.Zones[0].Serving || !report.Zones[1].Serving {
+		t.Fatalf("zones = %+v, want the Route53 zone unserved and the Cloudflare zone serving", report.Zones)
+	}
+	if len(report.Records) != 2 || report.Records[0].Provider != Cloudflare || !report.Records[0].Serving {
+		t.Fatalf("records = %+v, want the serving Cloudflare record first", report.Records)
+	}
+	if got, want := kinds(report.Findings), []FindingKind{ShadowZone, Conflict}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("findings = %+v, want %v", report.Findings, want)
+	}
+	if d := report.Findings[1].Detail; !strings.Contains(d, "Cloudflare zone example.com has CNAME shop.example.net, Route53 zone example.com has A 192.0.2.1") {
+		t.Errorf("conflict detail = %q", d)
+	}
+
+	out := report.Format()
+	for _, want := range []string{"Resolves to: CNAME shop.example.net -> 203.0.113.10", "Managed by: Cloudflare, which serves example.com", "shadow zone: Route53 zone example.com (Z1)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Format() missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCheckZoneScan(t *testing.T) {
+	req := ParseRequest("find dangling records in example.com and clean them up")
+	if !req.Scan || !req.Fix || req.Name != "example.com" {
+		t.Fatalf("request = %+v", req)
+	}
+	report := testAgent().Check(context.Background(), req)
+	if !report.Scan {
+		t.Fatal("a zone apex with a scan request should scan the zone")
+	}
+	var dangling []string
+	for _, f := range report.Findings {
+		if f.Kind == Dangling {
+			dangling = append(dangling, f.Record.Name)
+		}
+	}
+	if want := []string{"legacy.example.com", "old.example.com"}; !reflect.DeepEqual(dangling, want) {
+		t.Fatalf("dangling = %v, want %v", dangling, want)
+	}
+
+	c := BuildCorrections(report, "find dangling records in example.com and clean them up", testNow)
+	if len(c.Plans) != 1 {
+		t.Fatalf("plans = %+v, want only the Cloudflare plan", c.Plans)
+	}
+	plan := c.Plans[0]
+	if plan.Provider != "cloudflare" || len(plan.Commands) != 1 || strings.Join(plan.Commands[0].Args, " ") != "DELETE /zones/z1/dns_records/r2" {
+		t.Errorf("plan = %+v", plan)
+	}
+	notes := c.Format()
+	if !strings.Contains(notes, "old.example.com A alias in Route53 zone example.com is dangling but not served") {
+		t.Errorf("notes:\n%s", notes)
+	}
+}
+
+func TestBuildCorrectionsRoute53(t *testing.T) {
+	raw := json.RawMessage(`{"Name": "old.example.com.", "Type": "CNAME", "TTL": 300, "ResourceRecords": [{"Value": "gone.example.net"}]}`)
+	rec := Record{Provider: Route53, Zone: "example.com", ZoneID: "Z1", Name: "old.example.com", Type: "CNAME", Values: []string{"gone.example.net"}, Serving: true, raw: raw}
+	report := &Report{Findings: []Finding{{Kind: Dangling, Record: &rec}}}
+
+	c := BuildCorrections(report, "fix dangling records in example.com", testNow)
+	if len(c.Plans) != 1 || c.Plans[0].Provider != "aws" {
+		t.Fatalf("plans = %+v", c.Plans)
+	}
+	args := c.Plans[0].Commands[0].Args
+	if strings.Join(args[:4], " ") != "route53 change-resource-record-sets --hosted-zone-id Z1" || args[4] != "--change-batch" {
+		t.Fatalf("args = %v", args)
+	}
+	want := `{"Changes":[{"Action":"DELETE","ResourceRecordSet":{"Name":"old.example.com.","Type":"CNAME","TTL":300,"ResourceRecords":[{"Value":"gone.example.net"}]}}],"Comment":"Delete dangling records"}`
+	if args[5] != want {
+		t.Errorf("change batch = %s\nwant %s", args[5], want)
+	}
+}
+
+func TestCheckUnmanagedAndMissing(t *testing.T) {
+	agent := NewAgent(nil, nil, fakeResolver{
+		ns:    map[string][]string{"example.org": {"ns1.dnsimple.com."}},
+		hosts: map[string][]string{"www.example.org": {"198.51.100.7"}},
+	}, false)
+	report := agent.Check(context.Background(), Request{Name: "www.example.org"})
+	if report.Authority != "dnsimple.com" || len(report.Findings) != 1 || report.Findings[0].Kind != Unmanaged {
+		t.Errorf("report = %+v", report)
+	}
+
+	report = agent.Check(context.Background(), Request{Name: "nope.example.net"})
+	if got := kinds(report.Findings); !reflect.DeepEqual(got, []FindingKind{Missing}) {
+		t.Errorf("findings = %+v", report.Findings)
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     Request
+	}{
+		{"where does api.example.com actually resolve and who manages it", Request{Name: "api.example.com"}},
+		{"which provider serves Shop.Example.co.uk?", Request{Name: "shop.example.co.uk"}},
+		{"find dangling records in example.com", Request{Name: "example.com", Scan: true}},
+		{"reconcile dns for example.com between route53 and cloudflare and fix conflicts", Request{Name: "example.com", Fix: true}},
+	}
+	for _, tt := range tests {
+		if got := ParseRequest(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestIsDNSTruthQuestion(t *testing.T) {
+	yes := []string{
+		"where does api.example.com actually resolve and who manages it",
+		"who manages dns for example.com",
+		"find dangling records in example.com",
+		"is shop.example.com delegated to route53 or cloudflare",
+	}
+	no := []string{
+		"add a CNAME www pointing to example.pages.dev",
+		"export dns records for example.com",
+		"where does my lambda write logs",
+		"list route53 hosted zones",
+	}
+	for _, q := range yes {
+		if !IsDNSTruthQuestion(q) {
+			t.Errorf("IsDNSTruthQuestion(%q) = false, want true", q)
+		}
+	}
+	for _, q := range no {
+		if IsDNSTruthQuestion(q) {
+			t.Errorf("IsDNSTruthQuestion(%q) = true, want false", q)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package dnstruth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FindingKind is what is wrong with a name or zone
+type FindingKind string
+
+const (
+	// Unmanaged means the domain is delegated to neither Route53 nor
+	// Cloudflare
+	Unmanaged FindingKind = "unmanaged"
+	// ShadowZone means a zone exists for the domain but the domain is not
+	// delegated to it, so none of its records are served
+	ShadowZone FindingKind = "shadow zone"
+	// Conflict means two zones hold different records for the same name
+	Conflict FindingKind = "conflict"
+	// Dangling means a CNAME or alias points at a name that does not
+	// resolve, which can let someone else claim the target
+	Dangling FindingKind = "dangling"
+	// Mismatch means live DNS answers differently from the serving zone
+	Mismatch FindingKind = "mismatch"
+	// Missing means no zone holds the name and it does not resolve
+	Missing FindingKind = "missing"
+)
+
+// Finding is one problem the report calls out
+type Finding struct {
+	Kind   FindingKind
+	Detail string
+	// Record is the record a dangling finding is about
+	Record *Record
+}
+
+// findings compares the zones and records in report with each other and
+// with live DNS
+func (a *Agent) findings(ctx context.Context, report *Report) []Finding {
+	var out []Finding
+	anyServing := false
+	for _, z := range report.Zones {
+		anyServing = anyServing || z.Serving
+	}
+
+	if report.DelegatedZone != "" && !anyServing && report.Authority != Route53 && report.Authority != Cloudflare {
+		out = append(out, Finding{Kind: Unmanaged, Detail: fmt.Sprintf("%s is delegated to %s (%s), outside Route53 and Cloudflare",
+			report.DelegatedZone, report.Authority.Label(), strings.Join(report.NameServers, ", "))})
+	}
+	if report.DelegatedZone == "" {
+		out = append(out, Finding{Kind: Missing, Detail: fmt.Sprintf("no nameservers were found for %s or its parents, so none of its zones are served", report.Name)})
+	} else {
+		for _, z := range report.Zones {
+			if z.Serving || z.Private {
+				continue
+			}
+			out = append(out, Finding{Kind: ShadowZone, Detail: fmt.Sprintf("%s zone %s (%s) is not the one %s is delegated to, so its records are not served",
+				z.Provider.Label(), z.Name, z.ID, report.DelegatedZone)})
+		}
+	}
+
+	// Conflicts: each name's records in a zone that is not serving against
+	// the serving zone's, or between any two zones when none serves
+	byName := map[string][]Record{}
+	var names []string
+	for _, r := range report.Records {
+		if _, seen := byName[r.Name]; !seen {
+			names = append(names, r.Name)
+		}
+		byName[r.Name] = append(byName[r.Name], r)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sets := map[string][]Record{}
+		var zoneKeys []string
+		for _, r := range byName[name] {
+			key := r.Provider.Label() + " zone " + r.Zone
+			if _, seen := sets[key]; !seen {
+				zoneKeys = append(zoneKeys, key)
+			}
+			sets[key] = append(sets[key], r)
+		}
+		if len(zoneKeys) < 2 {
+			continue
+		}
+		base := zoneKeys[0]
+		for _, key := range zoneKeys[1:] {
+			if describe(sets[key]) != describe(sets[base]) {
+				out = append(out, Finding{Kind: Conflict, Detail: fmt.Sprintf("%s: %s has %s, %s has %s",
+					name, base, describe(sets[base]), key, describe(sets[key]))})
+			}
+		}
+	}
+
+	for i := range report.Records {
+		r := &report.Records[i]
+		target := r.Target()
+		if target == "" || a.resolves(ctx, target) {
+			continue
+		}
+		detail := fmt.Sprintf("%s %s in %s zone %s points at %s, which does not resolve", r.Name, recordType(*r), r.Provider.Label(), r.Zone, target)
+		if r.Serving {
+			detail += "; anyone who can claim that name can serve traffic for it"
+		}
+		out = append(out, Finding{Kind: Dangling, Detail: detail, Record: r})
+	}
+
+	if !report.Scan {
+		out = append(out, liveFindings(report)...)
+	}
+	return out
+}
+
+// liveFindings compares the live answer for the name with its records in
+// the serving zone
+func liveFindings(report *Report) []Finding {
+	var serving []Record
+	for _, r := range report.Records {
+		if r.Serving {
+			serving = append(serving, r)
+		}
+	}
+	if len(report.Records) == 0 && report.Answer.Err != nil && report.DelegatedZone != "" {
+		return []Finding{{Kind: Missing, Detail: fmt.Sprintf("%s does not resolve and no Route53 or Cloudflare zone has a record for it", report.Name)}}
+	}
+	var want []string
+	for _, r := range serving {
+		// Proxied records answer with Cloudflare's addresses, and aliases
+		// and CNAMEs with their target's
+		if r.Proxied || r.Alias || r.Type == "CNAME" {
+			return nil
+		}
+		want = append(want, r.Values...)
+	}
+	if len(want) == 0 {
+		return nil
+	}
+	sort.Strings(want)
+	if report.Answer.Err != nil {
+		return []Finding{{Kind: Mismatch, Detail: fmt.Sprintf("%s does not resolve, but the serving zone has %s", report.Name, strings.Join(want, ", "))}}
+	}
+	if strings.Join(want, ",") != strings.Join(report.Answer.Addresses, ",") {
+		return []Finding{{Kind: Mismatch, Detail: fmt.Sprintf("%s resolves to %s, but the serving zone has %s; resolvers may still be caching an older answer",
+			report.Name, strings.Join(report.Answer.Addresses, ", "), strings.Join(want, ", "))}}
+	}
+	return nil
+}
+
+// describe renders a zone's records for one name so two zones can be
+// compared
+func describe(records []Record) string {
+	var parts []string
+	for _, r := range records {
+		values := append([]string(nil), r.Values...)
+		sort.Strings(values)
+		parts = append(parts, recordType(r)+" "+strings.Join(values, ","))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
+
+// recordType is the record's type, marking Route53 aliases
+func recordType(r Record) string {
+	if r.Alias {
+		return r.Type + " alias"
+	}
+	return r.Type
+}
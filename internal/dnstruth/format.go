@@ -0,0 +1,107 @@
+package dnstruth
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+)
+
+// Format renders the live answer, the delegation, the zones and records
+// found, and the findings
+func (r *Report) Format() string {
+	var sb strings.Builder
+	if r.Scan {
+		fmt.Fprintf(&sb, "Zone %s\n", r.Name)
+	} else {
+		fmt.Fprintf(&sb, "%s\n", r.Name)
+		switch {
+		case r.Answer.Err != nil:
+			fmt.Fprintf(&sb, "  Resolves to: nothing (%v)\n", r.Answer.Err)
+		case r.Answer.CNAME != "":
+			fmt.Fprintf(&sb, "  Resolves to: CNAME %s -> %s\n", r.Answer.CNAME, strings.Join(r.Answer.Addresses, ", "))
+		default:
+			fmt.Fprintf(&sb, "  Resolves to: %s\n", strings.Join(r.Answer.Addresses, ", "))
+		}
+	}
+	if r.DelegatedZone == "" {
+		sb.WriteString("  Managed by: no nameservers found\n")
+	} else {
+		fmt.Fprintf(&sb, "  Managed by: %s, which serves %s (%s)\n", r.Authority.Label(), r.DelegatedZone, strings.Join(r.NameServers, ", "))
+	}
+
+	if len(r.Zones) > 0 {
+		sb.WriteString("\nZones:\n")
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  PROVIDER\tZONE\tID\tSERVED")
+		for _, z := range r.Zones {
+			served := "no"
+			switch {
+			case z.Private:
+				served = "private"
+			case z.Serving:
+				served = "yes"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", z.Provider.Label(), z.Name, z.ID, served)
+		}
+		tw.Flush()
+	}
+
+	if len(r.Records) > 0 {
+		sb.WriteString("\nRecords:\n")
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  NAME\tTYPE\tVALUE\tTTL\tPROVIDER\tSERVED")
+		for _, rec := range r.Records {
+			value := strings.Join(rec.Values, ", ")
+			if rec.Proxied {
+				value += " (proxied)"
+			}
+			served := "no"
+			if rec.Serving {
+				served = "yes"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", rec.Name, recordType(rec), value, ttl(rec.TTL), rec.Provider.Label(), served)
+		}
+		tw.Flush()
+	} else if len(r.Zones) > 0 && !r.Scan {
+		sb.WriteString("\nNo zone has an A, AAAA or CNAME record for this name.\n")
+	}
+
+	if len(r.Findings) > 0 {
+		sb.WriteString("\nFindings:\n")
+		for _, f := range r.Findings {
+			fmt.Fprintf(&sb, "  - %s: %s\n", f.Kind, f.Detail)
+		}
+	} else {
+		sb.WriteString("\nNo conflicting or dangling records found.\n")
+	}
+	for _, p := range []Provider{Route53, Cloudflare} {
+		if err, failed := r.Errors[p]; failed {
+			fmt.Fprintf(&sb, "%s: not read (%v)\n", p.Label(), err)
+		}
+	}
+	return sb.String()
+}
+
+// Format renders the notes; plans are printed by the caller
+func (c *Corrections) Format() string {
+	if len(c.Notes) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Notes:\n")
+	for _, note := range c.Notes {
+		fmt.Fprintf(&sb, "  - %s\n", note)
+	}
+	return sb.String()
+}
+
+func ttl(seconds int) string {
+	switch seconds {
+	case 0:
+		return "-"
+	case 1:
+		// Cloudflare's automatic TTL
+		return "auto"
+	}
+	return fmt.Sprintf("%d", seconds)
+}
@@ -0,0 +1,105 @@
+package dnstruth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// Corrections are the plans that delete dangling records from serving
+// zones, at most one per provider, and notes on the findings that need a
+// decision rather than a delete
+type Corrections struct {
+	Plans []*maker.Plan
+	Notes []string
+}
+
+// BuildCorrections plans the corrections of report's findings. Only
+// dangling records in a serving zone are deleted; records in zones that are
+// not served, conflicts and stale answers are left to notes.
+func BuildCorrections(report *Report, question string, now time.Time) *Corrections {
+	out := &Corrections{}
+	var r53Zones []string
+	r53Deletes := map[string][]json.RawMessage{}
+	var r53Names []string
+	var cfCmds []maker.Command
+	var cfNames []string
+
+	for _, f := range report.Findings {
+		switch f.Kind {
+		case Dangling:
+			r := f.Record
+			if !r.Serving {
+				out.Notes = append(out.Notes, fmt.Sprintf("%s %s in %s zone %s is dangling but not served; it goes away with the zone.", r.Name, recordType(*r), r.Provider.Label(), r.Zone))
+				continue
+			}
+			switch r.Provider {
+			case Route53:
+				if _, seen := r53Deletes[r.ZoneID]; !seen {
+					r53Zones = append(r53Zones, r.ZoneID)
+				}
+				var compact bytes.Buffer
+				if err := json.Compact(&compact, r.raw); err != nil {
+					out.Notes = append(out.Notes, fmt.Sprintf("Could not plan deleting %s %s: %v", r.Name, r.Type, err))
+					continue
+				}
+				r53Deletes[r.ZoneID] = append(r53Deletes[r.ZoneID], compact.Bytes())
+				r53Names = append(r53Names, r.Name)
+			case Cloudflare:
+				cfCmds = append(cfCmds, maker.Command{
+					Args:   []string{"DELETE", fmt.Sprintf("/zones/%s/dns_records/%s", r.ZoneID, r.ID)},
+					Reason: fmt.Sprintf("Delete %s %s, which points at %s that no longer resolves", r.Name, r.Type, r.Target()),
+				})
+				cfNames = append(cfNames, r.Name)
+			}
+		case Conflict:
+			out.Notes = append(out.Notes, fmt.Sprintf("Conflict on %s. Only the serving zone answers, so make the other zone match it before moving the domain, or delete the zone that is not used.", f.Detail))
+		case ShadowZone:
+			out.Notes = append(out.Notes, fmt.Sprintf("%s. Delete it if it is left over from a migration, or keep it in sync if a move is planned.", capitalize(f.Detail)))
+		case Mismatch:
+			out.Notes = append(out.Notes, capitalize(f.Detail)+".")
+		}
+	}
+
+	if len(r53Zones) > 0 {
+		var cmds []maker.Command
+		for _, zoneID := range r53Zones {
+			changes := make([]map[string]any, 0, len(r53Deletes[zoneID]))
+			for _, raw := range r53Deletes[zoneID] {
+				changes = append(changes, map[string]any{"Action": "DELETE", "ResourceRecordSet": json.RawMessage(raw)})
+			}
+			batch, _ := json.Marshal(map[string]any{"Comment": "Delete dangling records", "Changes": changes})
+			cmds = append(cmds, maker.Command{
+				Args:   []string{"route53", "change-resource-record-sets", "--hosted-zone-id", zoneID, "--change-batch", string(batch)},
+				Reason: fmt.Sprintf("Delete %d dangling record(s) from hosted zone %s", len(changes), zoneID),
+			})
+		}
+		out.Plans = append(out.Plans, correctionPlan("aws", fmt.Sprintf("Delete dangling Route53 records: %s", strings.Join(r53Names, ", ")), question, cmds, now))
+	}
+	if len(cfCmds) > 0 {
+		out.Plans = append(out.Plans, correctionPlan("cloudflare", fmt.Sprintf("Delete dangling Cloudflare records: %s", strings.Join(cfNames, ", ")), question, cfCmds, now))
+	}
+	return out
+}
+
+func correctionPlan(provider, summary, question string, cmds []maker.Command, now time.Time) *maker.Plan {
+	return &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  provider,
+		Question:  question,
+		Summary:   summary,
+		Commands:  cmds,
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
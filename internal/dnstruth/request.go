@@ -0,0 +1,46 @@
+package dnstruth
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Request is a question translated into a DNS check
+type Request struct {
+	// Name is the DNS name to check
+	Name string
+	// Scan asks for every record of the zone when Name is a zone apex
+	Scan bool
+	// Fix asks for plans that correct what the check finds
+	Fix bool
+}
+
+var (
+	nameRe = regexp.MustCompile(`(?i)\b((?:[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63})\b`)
+	// truthRe asks where a name resolves or who serves it, as opposed to
+	// adding or exporting records
+	truthRe = regexp.MustCompile(`(?i)\bwhere\s+(?:does|do|is|are)\b.*\b(?:resolve|resolving|point|pointing|go|served|hosted)\b|\bwho\s+(?:manages|hosts|serves|controls|owns)\b|\bactually\s+resolves?\b|\bwhich\s+(?:provider|nameservers?|dns)\b|\b(?:dangling|orphaned)\s+(?:dns|records?|cnames?)\b|\bconflicting\s+(?:dns|records?)\b|\bdns\s+(?:conflicts?|truth)\b|\breconcile\b.*\b(?:dns|route ?53|cloudflare)\b|\bdelegat(?:ed|ion)\b`)
+	scanRe  = regexp.MustCompile(`(?i)\b(?:all|every|whole|entire|any)\b.*\brecords?\b|\bdangling\b|\borphaned\b|\bzone\b`)
+	fixRe   = regexp.MustCompile(`(?i)\b(?:fix|correct|clean ?up|remove|delete|reconcile|plan)\b|\bclean\s+(?:\w+\s+)?up\b`)
+	// notNameRe are file names and provider hosts the name pattern would
+	// otherwise pick up
+	notNameRe = regexp.MustCompile(`(?i)\.(?:txt|json|yaml|yml|zone|db|csv)$|^(?:route53|cloudflare|amazonaws)\.com$`)
+)
+
+// ParseRequest reads the name to check from question
+func ParseRequest(question string) Request {
+	req := Request{Scan: scanRe.MatchString(question), Fix: fixRe.MatchString(question)}
+	for _, m := range nameRe.FindAllStringSubmatch(question, -1) {
+		if !notNameRe.MatchString(m[1]) {
+			req.Name = strings.ToLower(m[1])
+			break
+		}
+	}
+	return req
+}
+
+// IsDNSTruthQuestion reports whether question asks where a name resolves,
+// who manages it, or which of its records conflict or dangle
+func IsDNSTruthQuestion(question string) bool {
+	return truthRe.MatchString(question) && ParseRequest(question).Name != ""
+}
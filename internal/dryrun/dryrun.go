@@ -0,0 +1,126 @@
+// Package dryrun lets every executor honor the global --dry-run flag. An
+// executor calls Skip before running a command: when dry-run is on and the
+// command would change resources, Skip prints it instead, with its
+// arguments resolved and secrets masked. Read-only commands still run, so
+// the lookups that later arguments are built from return real values.
+package dryrun
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/redact"
+	"github.com/spf13/viper"
+)
+
+// Prefix starts every line a dry run prints in place of a command
+const Prefix = "[dry-run]"
+
+// ErrStopped is returned by executors whose next steps wait on or read
+// back what a skipped command would have created, such as a cluster create
+// followed by polling for it. The run ends there and is reported as a
+// successful dry run.
+var ErrStopped = errors.New("dry run stopped: later steps need the result of the skipped command")
+
+// Enabled reports whether --dry-run (or dry_run) is set for this run
+func Enabled() bool {
+	return viper.GetBool("dry_run")
+}
+
+// readOnlyVerbs are command words that only read, for tools the audit log
+// does not classify, such as hcloud and wrangler
+var readOnlyVerbs = map[string]bool{
+	"list": true, "ls": true, "describe": true, "get": true, "show": true,
+	"info": true, "version": true, "help": true, "whoami": true, "status": true,
+	"tail": true, "logs": true, "view": true, "inspect": true,
+}
+
+// Mutates reports whether a command can change resources. kubectl, helm,
+// eksctl, aws, gcloud and az use the audit log's rules. For other tools a
+// command counts as a change unless one of its first three subcommand
+// words only reads. A flag is assumed to take the next argument, so a flag
+// value such as the list in --name list is never read as a verb.
+func Mutates(tool string, args []string) bool {
+	tool = filepath.Base(tool)
+	switch tool {
+	case "kubectl", "helm", "eksctl", "aws", "gcloud", "az":
+		return audit.IsMutation(tool, args)
+	}
+	words := 0
+	for i := 0; i < len(args) && words < 3; i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "-") {
+			if !strings.Contains(arg, "=") && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+			}
+			continue
+		}
+		if readOnlyVerbs[strings.ToLower(arg)] {
+			return false
+		}
+		words++
+	}
+	return true
+}
+
+// Command formats a command line the way a dry run prints it: shell
+// quoted, with password, token and secret values masked
+func Command(tool string, args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, quote(filepath.Base(tool)))
+	for _, arg := range audit.RedactArgs(args) {
+		parts = append(parts, quote(arg))
+	}
+	return redact.Apply(strings.Join(parts, " "))
+}
+
+// Print writes the command a dry run skips
+func Print(w io.Writer, tool string, args []string) {
+	_, _ = fmt.Fprintf(w, "%s %s\n", Prefix, Command(tool, args))
+}
+
+// PrintInput writes a skipped command that reads input on stdin, naming
+// the input rather than printing it
+func PrintInput(w io.Writer, tool string, args []string, input string) {
+	_, _ = fmt.Fprintf(w, "%s %s < %s\n", Prefix, Command(tool, args), input)
+}
+
+// sensitiveScriptFlag matches password, secret and token flags and their
+// values in a shell script, such as kubeadm join --token
+var sensitiveScriptFlag = regexp.MustCompile(`(?i)(--[a-z-]*(?:password|secret|token|credential|private-key)[a-z-]*[ =])('[^']*'|"[^"]*"|[^\s\\]+)`)
+
+// PrintScript writes a script a dry run skips running on host
+func PrintScript(w io.Writer, host, script string) {
+	script = sensitiveScriptFlag.ReplaceAllString(script, "${1}"+redact.Placeholder)
+	_, _ = fmt.Fprintf(w, "%s ssh %s <<'EOF'\n%s\nEOF\n", Prefix, host, strings.TrimRight(redact.Apply(script), "\n"))
+}
+
+// Skip prints a command in place of running it when dry-run is on and the
+// command changes resources. Executors return without running it when
+// Skip returns true.
+func Skip(w io.Writer, tool string, args []string) bool {
+	if !Enabled() || !Mutates(tool, args) {
+		return false
+	}
+	Print(w, tool, args)
+	return true
+}
+
+// quote single-quotes arg when a shell would split or expand it
+func quote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(arg, " \t\n\"'`$&|;<>()*?{}\\!#~") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
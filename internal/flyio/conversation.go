@@ -1,15 +1,12 @@
 package flyio
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/bgdnvk/clanker/internal/convhistory"
 )
 
 // ConversationEntry represents a single Q&A exchange.
@@ -57,11 +54,7 @@ func (h *ConversationHistory) AddEntry(question, answer string) {
 		Answer:    answer,
 	}
 
-	h.Entries = append(h.Entries, entry)
-
-	if len(h.Entries) > MaxHistoryEntries {
-		h.Entries = h.Entries[len(h.Entries)-MaxHistoryEntries:]
-	}
+	h.Entries = convhistory.Append(h.Entries, entry, MaxHistoryEntries)
 }
 
 // GetRecentContext returns recent conversation context as a formatted string
@@ -85,108 +78,24 @@ func (h *ConversationHistory) GetRecentContext(maxEntries int) string {
 			sb.WriteString("\n")
 		}
 		sb.WriteString(fmt.Sprintf("Q: %s\n", entry.Question))
-		sb.WriteString(fmt.Sprintf("A: %s\n", truncateAnswer(entry.Answer, MaxAnswerLengthInContext)))
+		sb.WriteString(fmt.Sprintf("A: %s\n", convhistory.Truncate(entry.Answer, MaxAnswerLengthInContext)))
 	}
 
 	return sb.String()
 }
 
-// Save persists the conversation history to disk using atomic write (temp + rename).
+// Save persists the conversation history to ~/.clanker/conversations/flyio_<orgslug>.json
 func (h *ConversationHistory) Save() error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-
-	dir, err := conversationDir()
-	if err != nil {
-		return err
-	}
-
-	if err := secfile.EnsurePrivateDir(dir); err != nil {
-		return fmt.Errorf("failed to create conversation directory: %w", err)
-	}
-
-	if len(h.Entries) > MaxHistoryEntries {
-		h.Entries = h.Entries[len(h.Entries)-MaxHistoryEntries:]
-	}
-
-	data, err := json.MarshalIndent(h, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal conversation history: %w", err)
-	}
-
-	filename, err := h.filePath()
-	if err != nil {
-		return err
-	}
-
-	tmp := filename + ".tmp"
-	if err := secfile.WritePrivate(tmp, data); err != nil {
-		return fmt.Errorf("failed to write temp conversation file: %w", err)
-	}
-	if err := os.Rename(tmp, filename); err != nil {
-		_ = os.Remove(tmp)
-		return fmt.Errorf("failed to rename conversation file: %w", err)
-	}
-
-	return nil
+	h.Entries = convhistory.FitSize(h.Entries, convhistory.MaxBytes())
+	return convhistory.Save("flyio", h.OrgSlug, h)
 }
 
-// Load loads conversation history from disk.
+// Load loads conversation history from disk
 func (h *ConversationHistory) Load() error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-
-	path, err := h.filePath()
-	if err != nil {
-		return err
-	}
-	data, err := secfile.ReadPrivate(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return fmt.Errorf("failed to read conversation file: %w", err)
-	}
-
-	var loaded struct {
-		Entries []ConversationEntry `json:"entries"`
-		OrgSlug string              `json:"org_slug"`
-	}
-
-	if err := json.Unmarshal(data, &loaded); err != nil {
-		return fmt.Errorf("failed to parse conversation history: %w", err)
-	}
-
-	h.Entries = loaded.Entries
-	if strings.TrimSpace(loaded.OrgSlug) != "" {
-		h.OrgSlug = loaded.OrgSlug
-	}
-
-	return nil
-}
-
-// filePath returns the on-disk path for this history file.
-func (h *ConversationHistory) filePath() (string, error) {
-	dir, err := conversationDir()
-	if err != nil {
-		return "", err
-	}
-	return filepath.Join(dir, fmt.Sprintf("flyio_%s.json", secfile.SafeSlug(h.OrgSlug))), nil
-}
-
-// conversationDir returns ~/.clanker/conversations.
-func conversationDir() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-	return filepath.Join(home, ".clanker", "conversations"), nil
-}
-
-// truncateAnswer truncates text to maxLen characters, adding ellipsis if truncated.
-func truncateAnswer(text string, maxLen int) string {
-	if len(text) <= maxLen {
-		return text
-	}
-	return text[:maxLen] + "..."
+	_, err := convhistory.Load("flyio", h.OrgSlug, h)
+	return err
 }
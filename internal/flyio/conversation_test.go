@@ -90,13 +90,3 @@ func TestSaveLoadRoundTrip(t *testing.T) {
 		t.Errorf("first question = %q, want first question", loaded.Entries[0].Question)
 	}
 }
-
-func TestTruncateAnswer(t *testing.T) {
-	if got := truncateAnswer("hello", 100); got != "hello" {
-		t.Errorf("short answer should pass through, got %q", got)
-	}
-	got := truncateAnswer("hello world this is long", 5)
-	if got != "hello..." {
-		t.Errorf("truncated answer = %q, want hello...", got)
-	}
-}
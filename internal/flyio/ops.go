@@ -0,0 +1,190 @@
+package flyio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+)
+
+// OpsAPI is the part of Client the ops agent calls, so tests can stand in
+// for Fly.
+type OpsAPI interface {
+	RunAPIWithContext(ctx context.Context, method, endpoint, body string) (string, error)
+	RunFlyctlWithContext(ctx context.Context, args ...string) (string, error)
+}
+
+// maxMachineApps caps how many apps a machine listing without an app walks.
+const maxMachineApps = 20
+
+// Ops answers OpRequests. Reads run straight against Fly; changes are
+// built into plans by BuildOpPlan and only run through `ask --apply`.
+type Ops struct {
+	api OpsAPI
+	// flyctl is whether flyctl is on PATH; logs prefer it because the REST
+	// snapshot is a raw JSON dump.
+	flyctl bool
+}
+
+// NewOps returns an ops agent over api.
+func NewOps(api OpsAPI, flyctl bool) *Ops {
+	return &Ops{api: api, flyctl: flyctl}
+}
+
+// Read lists apps, or the machines, secret names or recent logs of req.App,
+// rendered as text.
+func (o *Ops) Read(ctx context.Context, req OpRequest) (string, error) {
+	switch req.Op {
+	case OpListApps:
+		apps, err := o.apps(ctx)
+		if err != nil {
+			return "", err
+		}
+		return formatOpApps(apps), nil
+	case OpListMachines:
+		return o.machines(ctx, req.App)
+	case OpListSecrets:
+		out, err := o.api.RunAPIWithContext(ctx, "GET", "/apps/"+url.PathEscape(req.App)+"/secrets", "")
+		if err != nil {
+			return "", err
+		}
+		var secrets []Secret
+		if err := json.Unmarshal([]byte(out), &secrets); err != nil {
+			return "", fmt.Errorf("failed to parse secrets of %s: %w", req.App, err)
+		}
+		return formatOpSecrets(req.App, secrets), nil
+	case OpLogs:
+		if o.flyctl {
+			return o.api.RunFlyctlWithContext(ctx, "logs", "--app", req.App, "--no-tail")
+		}
+		return o.api.RunAPIWithContext(ctx, "GET", "/apps/"+url.PathEscape(req.App)+"/logs", "")
+	}
+	return "", fmt.Errorf("%s is not a read operation", req.Op)
+}
+
+func (o *Ops) apps(ctx context.Context) ([]App, error) {
+	out, err := o.api.RunAPIWithContext(ctx, "GET", "/apps", "")
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Apps []App `json:"apps"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err == nil && resp.Apps != nil {
+		return resp.Apps, nil
+	}
+	var bare []App
+	if err := json.Unmarshal([]byte(out), &bare); err != nil {
+		return nil, fmt.Errorf("failed to parse apps: %w", err)
+	}
+	return bare, nil
+}
+
+// machines lists app's machines, or every app's when app is empty.
+func (o *Ops) machines(ctx context.Context, app string) (string, error) {
+	names := []string{app}
+	omitted := 0
+	if app == "" {
+		apps, err := o.apps(ctx)
+		if err != nil {
+			return "", err
+		}
+		names = names[:0]
+		for _, a := range apps {
+			names = append(names, a.Name)
+		}
+		if len(names) > maxMachineApps {
+			omitted = len(names) - maxMachineApps
+			names = names[:maxMachineApps]
+		}
+	}
+
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "APP\tMACHINE\tNAME\tSTATE\tREGION\tSIZE\tIMAGE")
+	var failed []string
+	count := 0
+	for _, name := range names {
+		out, err := o.api.RunAPIWithContext(ctx, "GET", "/apps/"+url.PathEscape(name)+"/machines", "")
+		var machines []Machine
+		if err == nil {
+			err = json.Unmarshal([]byte(out), &machines)
+		}
+		if err != nil {
+			if app != "" {
+				return "", err
+			}
+			failed = append(failed, fmt.Sprintf("%s: not read (%v)", name, err))
+			continue
+		}
+		for _, m := range machines {
+			image := ""
+			if m.Config != nil {
+				image = m.Config.Image
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, m.ID, m.Name, m.State, m.Region, machineSize(m), image)
+			count++
+		}
+	}
+	tw.Flush()
+
+	if count == 0 {
+		sb.Reset()
+		if app != "" {
+			fmt.Fprintf(&sb, "No machines for app %s.\n", app)
+		} else {
+			sb.WriteString("No machines found.\n")
+		}
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&sb, "(%d more apps not listed; name one with \"app <name>\")\n", omitted)
+	}
+	for _, f := range failed {
+		sb.WriteString(f + "\n")
+	}
+	return sb.String(), nil
+}
+
+// machineSize renders a machine's guest the way `flyctl scale vm` names it.
+func machineSize(m Machine) string {
+	if m.Config == nil || m.Config.Guest == nil {
+		return "-"
+	}
+	g := m.Config.Guest
+	kind := g.CPUKind
+	if kind == "" {
+		kind = "shared"
+	}
+	return fmt.Sprintf("%s-cpu-%dx/%dMB", kind, g.CPUs, g.MemoryMB)
+}
+
+func formatOpApps(apps []App) string {
+	if len(apps) == 0 {
+		return "No Fly apps found.\n"
+	}
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "APP\tORG\tSTATUS\tHOSTNAME")
+	for _, a := range apps {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", a.Name, a.Organization.Slug, a.Status, a.Hostname)
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+func formatOpSecrets(app string, secrets []Secret) string {
+	if len(secrets) == 0 {
+		return fmt.Sprintf("No secrets for app %s.\n", app)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Secrets of %s (names and digests only):\n", app)
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  NAME\tDIGEST\tCREATED")
+	for _, s := range secrets {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", s.Name, s.Digest, s.CreatedAt)
+	}
+	tw.Flush()
+	return sb.String()
+}
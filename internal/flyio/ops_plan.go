@@ -0,0 +1,133 @@
+package flyio
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// SecretVariablePrefix prefixes the environment variables a secrets plan
+// reads its values from at apply time, e.g. FLY_SECRET_DATABASE_URL, so a
+// value is never written to a plan.
+const SecretVariablePrefix = "FLY_SECRET_"
+
+// OpPlan is a change planned from an OpRequest, with the notes the caller
+// prints next to it.
+type OpPlan struct {
+	Plan  *maker.Plan
+	Notes []string
+}
+
+// BuildOpPlan plans a deploy, scale or secrets change as flyctl commands.
+func BuildOpPlan(req OpRequest, question string, now time.Time) (*OpPlan, error) {
+	if req.App == "" && req.Op != OpDeploy {
+		return nil, fmt.Errorf("name the Fly app to change, e.g. \"app my-api\"")
+	}
+	out := &OpPlan{}
+	var summary string
+	var cmds []maker.Command
+
+	switch req.Op {
+	case OpDeploy:
+		args := []string{"flyctl", "deploy", req.Path}
+		if req.App != "" {
+			args = append(args, "--app", req.App)
+		}
+		source := "the Dockerfile in " + req.Path
+		switch {
+		case req.Image != "":
+			args = append(args, "--image", req.Image)
+			source = "image " + req.Image
+		case req.Dockerfile != "":
+			args = append(args, "--dockerfile", req.Dockerfile)
+			source = req.Dockerfile
+		}
+		if req.Region != "" {
+			args = append(args, "--region", req.Region)
+		}
+		if req.Strategy != "" {
+			args = append(args, "--strategy", req.Strategy)
+		}
+		target := req.App
+		if target == "" {
+			target = "the app in fly.toml"
+			out.Notes = append(out.Notes, fmt.Sprintf("No app was named, so flyctl deploys the app in %s/fly.toml; apply from the directory the plan was made for.", req.Path))
+		}
+		summary = fmt.Sprintf("Deploy %s from %s", target, source)
+		cmds = append(cmds, maker.Command{Args: args, Reason: summary})
+	case OpScale:
+		if req.Count == 0 && req.VMSize == "" && req.MemoryMB == 0 {
+			return nil, fmt.Errorf("say what to scale %s to: a machine count, a VM size such as shared-cpu-2x, or memory such as 1gb", req.App)
+		}
+		var parts []string
+		if req.Count > 0 {
+			args := []string{"flyctl", "scale", "count", strconv.Itoa(req.Count), "--app", req.App}
+			if req.Region != "" {
+				args = append(args, "--region", req.Region)
+			}
+			args = append(args, "--yes")
+			where := ""
+			if req.Region != "" {
+				where = " in " + req.Region
+			}
+			cmds = append(cmds, maker.Command{Args: args, Reason: fmt.Sprintf("Run %d machine(s) of %s%s", req.Count, req.App, where)})
+			parts = append(parts, fmt.Sprintf("%d machine(s)%s", req.Count, where))
+		}
+		switch {
+		case req.VMSize != "":
+			args := []string{"flyctl", "scale", "vm", req.VMSize, "--app", req.App}
+			size := req.VMSize
+			if req.MemoryMB > 0 {
+				args = append(args, "--vm-memory", strconv.Itoa(req.MemoryMB))
+				size += fmt.Sprintf(" with %dMB", req.MemoryMB)
+			}
+			cmds = append(cmds, maker.Command{Args: args, Reason: fmt.Sprintf("Resize the machines of %s to %s", req.App, size)})
+			parts = append(parts, size)
+		case req.MemoryMB > 0:
+			cmds = append(cmds, maker.Command{
+				Args:   []string{"flyctl", "scale", "memory", strconv.Itoa(req.MemoryMB), "--app", req.App},
+				Reason: fmt.Sprintf("Give the machines of %s %dMB of memory", req.App, req.MemoryMB),
+			})
+			parts = append(parts, fmt.Sprintf("%dMB", req.MemoryMB))
+		}
+		if req.VMSize != "" || req.MemoryMB > 0 {
+			out.Notes = append(out.Notes, "Resizing restarts each machine of the app.")
+		}
+		summary = fmt.Sprintf("Scale %s to %s", req.App, strings.Join(parts, ", "))
+	case OpSetSecrets:
+		if len(req.Secrets) == 0 {
+			return nil, fmt.Errorf("name the secrets to set in capitals, e.g. DATABASE_URL")
+		}
+		args := []string{"flyctl", "secrets", "set", "--app", req.App}
+		var vars []string
+		for _, name := range req.Secrets {
+			args = append(args, fmt.Sprintf("%s=<%s%s>", name, SecretVariablePrefix, name))
+			vars = append(vars, SecretVariablePrefix+name)
+		}
+		summary = fmt.Sprintf("Set %s on %s", strings.Join(req.Secrets, ", "), req.App)
+		cmds = append(cmds, maker.Command{Args: args, Reason: summary + " and restart its machines"})
+		out.Notes = append(out.Notes, fmt.Sprintf("Export %s before applying. Values are piped to flyctl at apply time and never written to the plan.", strings.Join(vars, ", ")))
+	case OpUnsetSecrets:
+		if len(req.Secrets) == 0 {
+			return nil, fmt.Errorf("name the secrets to remove in capitals, e.g. DATABASE_URL")
+		}
+		args := append([]string{"flyctl", "secrets", "unset", "--app", req.App}, req.Secrets...)
+		summary = fmt.Sprintf("Remove %s from %s", strings.Join(req.Secrets, ", "), req.App)
+		cmds = append(cmds, maker.Command{Args: args, Reason: summary + " and restart its machines"})
+	default:
+		return nil, fmt.Errorf("%s does not change anything", req.Op)
+	}
+
+	out.Plan = &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "flyio",
+		Question:  question,
+		Summary:   summary,
+		Commands:  cmds,
+	}
+	return out, nil
+}
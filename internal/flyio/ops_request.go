@@ -0,0 +1,168 @@
+package flyio
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/askparse"
+)
+
+// Op is a Fly operation an ask question can be answered with directly,
+// without going through the LLM context agent.
+type Op string
+
+const (
+	OpListApps     Op = "list-apps"
+	OpListMachines Op = "list-machines"
+	OpLogs         Op = "logs"
+	OpListSecrets  Op = "list-secrets"
+	OpDeploy       Op = "deploy"
+	OpScale        Op = "scale"
+	OpSetSecrets   Op = "set-secrets"
+	OpUnsetSecrets Op = "unset-secrets"
+)
+
+// Mutates reports whether the op changes the app, in which case it is
+// planned rather than run.
+func (o Op) Mutates() bool {
+	switch o {
+	case OpDeploy, OpScale, OpSetSecrets, OpUnsetSecrets:
+		return true
+	}
+	return false
+}
+
+// OpRequest is a question translated into one Fly operation.
+type OpRequest struct {
+	Op  Op
+	App string
+	// Path is the build context of a deploy, "." unless the question names
+	// a directory.
+	Path       string
+	Dockerfile string
+	Image      string
+	Strategy   string
+	Region     string
+	// Count is the machine count of a scale, 0 when unchanged.
+	Count int
+	// VMSize is the machine size preset of a scale, e.g. shared-cpu-2x.
+	VMSize   string
+	MemoryMB int
+	// Secrets are the secret names to set or unset. Values are never read
+	// from the question.
+	Secrets []string
+}
+
+var (
+	opAppNames = askparse.Names{
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?:^|\s)(?:--app|-a)[\s=]+([a-z0-9][a-z0-9-]*)`),
+			regexp.MustCompile(`(?i)\b(?:app|application)\s+(?:named\s+|called\s+)?["'` + "`" + `]?([a-z0-9][a-z0-9-]*)`),
+			regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9-]*)\s+(?:fly\s+)?app\b`),
+		},
+		Skip: askparse.NotNames("each", "every", "fly", "flyio",
+			"logs", "secrets", "machines", "deploy", "scale", "list", "show"),
+	}
+
+	opSecretsRe = regexp.MustCompile(`(?i)\bsecrets?\b`)
+	opSetRe     = regexp.MustCompile(`(?i)\b(?:set|add|create|update|rotate|put|change)\b`)
+	opUnsetRe   = regexp.MustCompile(`(?i)\b(?:unset|remove|delete|drop)\b`)
+	opScaleRe   = regexp.MustCompile(`(?i)\bscale\b|\bresize\b`)
+	opLogsRe    = regexp.MustCompile(`(?i)\blogs?\b`)
+	opDeployRe  = regexp.MustCompile(`(?i)\b(?:deploy|redeploy|ship)\b`)
+	opMachineRe = regexp.MustCompile(`(?i)\bmachines?\b|\bvms?\b`)
+	opAppsRe    = regexp.MustCompile(`(?i)\bapps\b`)
+	opReadRe    = regexp.MustCompile(`(?i)\b(?:list|show|get|what|which|how\s+many|are|running|status)\b`)
+
+	opCountRe  = regexp.MustCompile(`(?i)\b(?:to|count)\s+(\d+)\b|\b(\d+)\s+(?:machines?|instances?|copies|replicas)\b`)
+	opVMRe     = regexp.MustCompile(`(?i)\b((?:shared-cpu|performance)-\d+x|a10|a100-40gb|a100-80gb|l40s)\b`)
+	opMemoryRe = regexp.MustCompile(`(?i)\b(\d+)\s*(mb|gb)\b`)
+	opRegionRe = regexp.MustCompile(`(?i)\b(?:in|region|to)\s+([a-z]{3})\b`)
+
+	opImageRe      = regexp.MustCompile(`(?i)\bimage\s+(\S+)|\b((?:[a-z0-9.-]+\.[a-z]{2,}(?::\d+)?/)?[a-z0-9._-]+(?:/[a-z0-9._-]+)+:[a-z0-9._-]+)\b`)
+	opDockerfileRe = regexp.MustCompile(`(\S*Dockerfile[\w.-]*)`)
+	opPathRe       = regexp.MustCompile(`\bfrom\s+(\.{1,2}(?:/\S*)?|/\S+)`)
+	opStrategyRe   = regexp.MustCompile(`(?i)\b(rolling|immediate|canary|bluegreen|blue-green)\b`)
+)
+
+// flyRegions are Fly's region codes, so "in the" is not taken for one.
+var flyRegions = map[string]bool{
+	"ams": true, "arn": true, "atl": true, "bog": true, "bom": true, "bos": true, "cdg": true, "den": true,
+	"dfw": true, "ewr": true, "eze": true, "fra": true, "gdl": true, "gig": true, "gru": true, "hkg": true,
+	"iad": true, "jnb": true, "lax": true, "lhr": true, "mad": true, "mia": true, "nrt": true, "ord": true,
+	"otp": true, "phx": true, "qro": true, "scl": true, "sea": true, "sin": true, "sjc": true, "syd": true,
+	"waw": true, "yul": true, "yyz": true,
+}
+
+// ParseOpRequest reads the Fly operation question asks for. The zero
+// OpRequest means the question is better answered by the context agent.
+func ParseOpRequest(question string) OpRequest {
+	req := OpRequest{App: strings.ToLower(opAppNames.Find(question))}
+	switch {
+	case opSecretsRe.MatchString(question):
+		req.Secrets = askparse.KeyNames(question)
+		switch {
+		case opUnsetRe.MatchString(question):
+			req.Op = OpUnsetSecrets
+		case opSetRe.MatchString(question):
+			req.Op = OpSetSecrets
+		case req.App != "":
+			req.Op = OpListSecrets
+		}
+	case opScaleRe.MatchString(question):
+		req.Op = OpScale
+		if m := opMemoryRe.FindStringSubmatch(question); m != nil {
+			req.MemoryMB, _ = strconv.Atoi(m[1])
+			if strings.EqualFold(m[2], "gb") {
+				req.MemoryMB *= 1024
+			}
+		}
+		// "to 2gb" is memory, not a count
+		if m := opCountRe.FindStringSubmatch(opMemoryRe.ReplaceAllString(question, "")); m != nil {
+			req.Count, _ = strconv.Atoi(m[1] + m[2])
+		}
+		if m := opVMRe.FindStringSubmatch(question); m != nil {
+			req.VMSize = strings.ToLower(m[1])
+		}
+	case opLogsRe.MatchString(question):
+		if req.App != "" {
+			req.Op = OpLogs
+		}
+	case opDeployRe.MatchString(question):
+		req.Op = OpDeploy
+		req.Path = "."
+		if m := opPathRe.FindStringSubmatch(question); m != nil {
+			req.Path = strings.TrimRight(m[1], ".,;")
+		}
+		if m := opDockerfileRe.FindStringSubmatch(question); m != nil {
+			req.Dockerfile = strings.TrimRight(m[1], ".,;")
+			// "from ./api/Dockerfile" names the file, not the build context
+			if req.Path == req.Dockerfile {
+				req.Path = "."
+			}
+		}
+		if m := opImageRe.FindStringSubmatch(question); m != nil {
+			req.Image = strings.TrimRight(m[1]+m[2], ".,;")
+		}
+		if m := opStrategyRe.FindStringSubmatch(question); m != nil {
+			req.Strategy = strings.ReplaceAll(strings.ToLower(m[1]), "-", "")
+		}
+	case opMachineRe.MatchString(question) && opReadRe.MatchString(question):
+		req.Op = OpListMachines
+	case opAppsRe.MatchString(question) && opReadRe.MatchString(question):
+		req.Op = OpListApps
+	}
+	if req.Op == OpScale || req.Op == OpDeploy {
+		for _, m := range opRegionRe.FindAllStringSubmatch(question, -1) {
+			if code := strings.ToLower(m[1]); flyRegions[code] {
+				req.Region = code
+				break
+			}
+		}
+	}
+	if req.Op == "" || (req.Op.Mutates() && askparse.AsksHowTo(question)) {
+		return OpRequest{}
+	}
+	return req
+}
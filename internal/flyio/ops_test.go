@@ -0,0 +1,156 @@
+package flyio
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// fakeOpsAPI answers API calls by method and endpoint and records flyctl
+// calls
+type fakeOpsAPI struct {
+	api    map[string]string
+	flyctl [][]string
+}
+
+func (f *fakeOpsAPI) RunAPIWithContext(ctx context.Context, method, endpoint, body string) (string, error) {
+	if out, ok := f.api[method+" "+endpoint]; ok {
+		return out, nil
+	}
+	return "", errors.New("unexpected call: " + method + " " + endpoint)
+}
+
+func (f *fakeOpsAPI) RunFlyctlWithContext(ctx context.Context, args ...string) (string, error) {
+	f.flyctl = append(f.flyctl, args)
+	return "2026-10-15T11:59:00Z app[1] iad [info] listening on :8080\n", nil
+}
+
+func TestParseOpRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     OpRequest
+	}{
+		{"list my fly apps", OpRequest{Op: OpListApps}},
+		{"show machines for the api app on fly", OpRequest{Op: OpListMachines, App: "api"}},
+		{"show fly logs for app web-prod", OpRequest{Op: OpLogs, App: "web-prod"}},
+		{"which secrets does fly app billing have", OpRequest{Op: OpListSecrets, App: "billing"}},
+		{"deploy app api to fly from ./services/api with Dockerfile.prod", OpRequest{Op: OpDeploy, App: "api", Path: "./services/api", Dockerfile: "Dockerfile.prod"}},
+		{"deploy registry.fly.io/api:v42 to the api app on fly in iad using a rolling strategy", OpRequest{Op: OpDeploy, App: "api", Path: ".", Image: "registry.fly.io/api:v42", Region: "iad", Strategy: "rolling"}},
+		{"scale the api fly app to 3 machines in ams", OpRequest{Op: OpScale, App: "api", Count: 3, Region: "ams"}},
+		{"scale fly app worker to performance-2x with 4gb", OpRequest{Op: OpScale, App: "worker", VMSize: "performance-2x", MemoryMB: 4096}},
+		{"set the DATABASE_URL and REDIS_URL secrets on fly app api", OpRequest{Op: OpSetSecrets, App: "api", Secrets: []string{"DATABASE_URL", "REDIS_URL"}}},
+		{"remove secret OLD_KEY from the api app on fly", OpRequest{Op: OpUnsetSecrets, App: "api", Secrets: []string{"OLD_KEY"}}},
+	}
+	for _, tt := range tests {
+		if got := ParseOpRequest(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseOpRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+
+	for _, q := range []string{
+		"how do I deploy a Dockerfile to fly",
+		"why is my fly app slow",
+		"show fly logs",
+	} {
+		if got := ParseOpRequest(q); got.Op != "" {
+			t.Errorf("ParseOpRequest(%q) = %+v, want it left to the context agent", q, got)
+		}
+	}
+}
+
+func TestOpsReadMachines(t *testing.T) {
+	api := &fakeOpsAPI{api: map[string]string{
+		"GET /apps": `{"apps": [{"name": "api"}, {"name": "worker"}]}`,
+		"GET /apps/api/machines": `[{"id": "148e", "name": "api-1", "state": "started", "region": "iad",
+			"config": {"image": "registry.fly.io/api:v42", "guest": {"cpu_kind": "shared", "cpus": 1, "memory_mb": 256}}}]`,
+	}}
+	out, err := NewOps(api, false).Read(context.Background(), OpRequest{Op: OpListMachines})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"api  148e", "shared-cpu-1x/256MB", "registry.fly.io/api:v42", "worker: not read"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestOpsReadLogsAndSecrets(t *testing.T) {
+	api := &fakeOpsAPI{api: map[string]string{
+		"GET /apps/api/secrets": `[{"name": "DATABASE_URL", "digest": "abc123"}]`,
+	}}
+	ops := NewOps(api, true)
+	if _, err := ops.Read(context.Background(), OpRequest{Op: OpLogs, App: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"logs", "--app", "api", "--no-tail"}}; !reflect.DeepEqual(api.flyctl, want) {
+		t.Errorf("flyctl calls = %v, want %v", api.flyctl, want)
+	}
+	out, err := ops.Read(context.Background(), OpRequest{Op: OpListSecrets, App: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "DATABASE_URL  abc123") {
+		t.Errorf("secrets output:\n%s", out)
+	}
+}
+
+func TestBuildOpPlan(t *testing.T) {
+	tests := []struct {
+		req      OpRequest
+		wantArgs [][]string
+		summary  string
+	}{
+		{
+			OpRequest{Op: OpDeploy, App: "api", Path: "./services/api", Dockerfile: "Dockerfile.prod", Strategy: "rolling"},
+			[][]string{{"flyctl", "deploy", "./services/api", "--app", "api", "--dockerfile", "Dockerfile.prod", "--strategy", "rolling"}},
+			"Deploy api from Dockerfile.prod",
+		},
+		{
+			OpRequest{Op: OpScale, App: "api", Count: 3, Region: "ams", VMSize: "shared-cpu-2x", MemoryMB: 1024},
+			[][]string{
+				{"flyctl", "scale", "count", "3", "--app", "api", "--region", "ams", "--yes"},
+				{"flyctl", "scale", "vm", "shared-cpu-2x", "--app", "api", "--vm-memory", "1024"},
+			},
+			"Scale api to 3 machine(s) in ams, shared-cpu-2x with 1024MB",
+		},
+		{
+			OpRequest{Op: OpSetSecrets, App: "api", Secrets: []string{"DATABASE_URL"}},
+			[][]string{{"flyctl", "secrets", "set", "--app", "api", "DATABASE_URL=<FLY_SECRET_DATABASE_URL>"}},
+			"Set DATABASE_URL on api",
+		},
+		{
+			OpRequest{Op: OpUnsetSecrets, App: "api", Secrets: []string{"OLD_KEY"}},
+			[][]string{{"flyctl", "secrets", "unset", "--app", "api", "OLD_KEY"}},
+			"Remove OLD_KEY from api",
+		},
+	}
+	for _, tt := range tests {
+		out, err := BuildOpPlan(tt.req, "q", testNow)
+		if err != nil {
+			t.Fatalf("BuildOpPlan(%+v): %v", tt.req, err)
+		}
+		var args [][]string
+		for _, c := range out.Plan.Commands {
+			args = append(args, c.Args)
+		}
+		if !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("BuildOpPlan(%+v) args = %v, want %v", tt.req, args, tt.wantArgs)
+		}
+		if out.Plan.Provider != "flyio" || out.Plan.Summary != tt.summary {
+			t.Errorf("plan = %q %q, want flyio %q", out.Plan.Provider, out.Plan.Summary, tt.summary)
+		}
+	}
+
+	if _, err := BuildOpPlan(OpRequest{Op: OpScale, App: "api"}, "q", testNow); err == nil {
+		t.Error("a scale with no target should fail")
+	}
+	if _, err := BuildOpPlan(OpRequest{Op: OpSetSecrets, Secrets: []string{"A_B"}}, "q", testNow); err == nil {
+		t.Error("a secrets change with no app should fail")
+	}
+}
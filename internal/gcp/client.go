@@ -66,6 +66,11 @@ func NewClient(projectID string, debug bool) (*Client, error) {
 	return &Client{projectID: projectID, debug: debug}, nil
 }
 
+// ProjectID returns the project the client queries
+func (c *Client) ProjectID() string {
+	return c.projectID
+}
+
 // BackendGCPCredentials represents GCP credentials from the backend
 type BackendGCPCredentials struct {
 	ProjectID          string
@@ -166,6 +171,12 @@ func (c *Client) execGcloud(ctx context.Context, args ...string) (string, error)
 	return "", fmt.Errorf("gcloud command failed: %w, stderr: %s%s", lastErr, lastStderr, gcloudErrorHint(lastStderr))
 }
 
+// ExecCLI runs a gcloud command in the client's project, retrying
+// transient failures
+func (c *Client) ExecCLI(ctx context.Context, args []string) (string, error) {
+	return c.execGcloud(ctx, args...)
+}
+
 func isRetryableGcloudError(stderr string) bool {
 	lower := strings.ToLower(stderr)
 	if strings.Contains(lower, "rate") && strings.Contains(lower, "limit") {
@@ -203,6 +214,13 @@ func gcloudErrorHint(stderr string) string {
 	}
 }
 
+// gkeClustersFormat lists GKE clusters with the settings that decide how
+// they are upgraded and sized
+const gkeClustersFormat = "table(name,location,status,currentMasterVersion:label=MASTER_VERSION,autopilot.enabled:label=AUTOPILOT,releaseChannel.channel:label=RELEASE_CHANNEL,autoscaling.enableNodeAutoprovisioning:label=AUTOPROVISIONING)"
+
+// gkeMaintenanceFormat lists when each GKE cluster may be auto-upgraded
+const gkeMaintenanceFormat = "table(name,location,releaseChannel.channel:label=RELEASE_CHANNEL,maintenancePolicy.window.dailyMaintenanceWindow.startTime:label=DAILY_START_UTC,maintenancePolicy.window.recurringWindow.window.startTime:label=RECURRING_START,maintenancePolicy.window.recurringWindow.recurrence:label=RECURRENCE,maintenancePolicy.window.maintenanceExclusions:label=EXCLUSIONS)"
+
 func (c *Client) GetRelevantContext(ctx context.Context, question string) (string, error) {
 	questionLower := strings.ToLower(strings.TrimSpace(question))
 
@@ -239,7 +257,8 @@ func (c *Client) GetRelevantContext(ctx context.Context, question string) (strin
 		{name: "Load Balancers", args: []string{"compute", "forwarding-rules", "list", "--format", "table(name,region,IPAddress,IPProtocol,portRange,target)"}, keys: []string{"cloud load balancing", "gcp load balancer"}},
 		{name: "Cloud Armor Policies", args: []string{"compute", "security-policies", "list", "--format", "table(name,description)"}, keys: []string{"cloud armor", "gcp armor"}},
 		{name: "Cloud DNS Zones", args: []string{"dns", "managed-zones", "list", "--format", "table(name,dnsName,visibility)"}, keys: []string{"cloud dns", "gcp dns"}},
-		{name: "GKE Clusters", args: []string{"container", "clusters", "list", "--format", "table(name,location,status,masterVersion)"}, keys: []string{"gke", "kubernetes engine"}},
+		{name: "GKE Clusters", args: []string{"container", "clusters", "list", "--format", gkeClustersFormat}, keys: []string{"gke", "kubernetes engine"}},
+		{name: "GKE Maintenance Windows", args: []string{"container", "clusters", "list", "--format", gkeMaintenanceFormat}, keys: []string{"maintenance window", "auto-upgrade", "auto upgrade", "release channel", "gke upgrade"}},
 		{name: "Cloud SQL Instances", args: []string{"sql", "instances", "list", "--format", "table(name,region,databaseVersion,state)"}, keys: []string{"cloud sql", "cloudsql"}},
 		{name: "AlloyDB Clusters", args: []string{"alloydb", "clusters", "list", "--region", "us-central1", "--format", "table(name,network,clusterType,createTime)"}, keys: []string{"alloydb", "alloy db"}},
 		{name: "BigQuery Datasets", args: []string{"bigquery", "datasets", "list", "--format", "table(id,location)"}, keys: []string{"bigquery"}},
@@ -199,7 +199,8 @@ func (c *Client) sdkGKEClusters(ctx context.Context) (string, error) {
 			continue
 		}
 		for _, cluster := range resp.Clusters {
-			lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%s", cluster.GetName(), cluster.GetLocation(), cluster.GetStatus().String(), cluster.GetCurrentMasterVersion()))
+			lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%s\t%t\t%s\t%t", cluster.GetName(), cluster.GetLocation(), cluster.GetStatus().String(), cluster.GetCurrentMasterVersion(),
+				cluster.GetAutopilot().GetEnabled(), cluster.GetReleaseChannel().GetChannel().String(), cluster.GetAutoscaling().GetEnableNodeAutoprovisioning()))
 		}
 	}
 
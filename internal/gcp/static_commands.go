@@ -324,7 +324,7 @@ Supported resources:
 				}
 				fmt.Print(result)
 			case "gke", "clusters":
-				result, err := exec("container", "clusters", "list", "--format", "table(name,location,status,masterVersion)")
+				result, err := exec("container", "clusters", "list", "--format", gkeClustersFormat)
 				if err != nil {
 					return err
 				}
@@ -0,0 +1,332 @@
+// Package actions changes GitHub Actions runs: it triggers workflow_dispatch
+// runs, re-runs failed jobs, cancels runs and approves pending deployments.
+// Every change is a maker plan to review and apply, built only after the
+// repository, branch, workflow and run it names are confirmed to exist and
+// to be in a state the change applies to.
+package actions
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Runner runs a gh CLI command and returns its output
+type Runner func(ctx context.Context, args []string) (string, error)
+
+// Agent reads workflows and runs and builds plans that change them
+type Agent struct {
+	run   Runner
+	debug bool
+}
+
+// NewAgent creates a GitHub Actions agent that calls GitHub through run
+func NewAgent(run Runner, debug bool) *Agent {
+	return &Agent{run: run, debug: debug}
+}
+
+// Workflow is a workflow file with the inputs its workflow_dispatch
+// trigger takes
+type Workflow struct {
+	ID    int64
+	Name  string
+	Path  string
+	State string
+	// Dispatchable is false when the workflow has no workflow_dispatch
+	// trigger on the ref it was read from
+	Dispatchable bool
+	Inputs       map[string]Input
+}
+
+// File is the workflow's file name, which gh workflow run accepts
+func (w Workflow) File() string {
+	return path.Base(w.Path)
+}
+
+// Input is a workflow_dispatch input
+type Input struct {
+	Required bool
+	Default  string
+	Options  []string
+}
+
+// Run is a workflow run
+type Run struct {
+	ID         int64
+	Number     int
+	Attempt    int
+	Name       string
+	Path       string
+	Status     string
+	Conclusion string
+	HeadBranch string
+	HeadSHA    string
+	Event      string
+	URL        string
+	CreatedAt  time.Time
+}
+
+// Label names the run in plan summaries
+func (r Run) Label() string {
+	return fmt.Sprintf("%s #%d (run %d)", r.Name, r.Number, r.ID)
+}
+
+// PendingDeployment is an environment a run waits on for approval
+type PendingDeployment struct {
+	EnvironmentID int64
+	Environment   string
+	CanApprove    bool
+	// WaitTimer is the environment's wait timer in minutes
+	WaitTimer int
+}
+
+// runJSON runs gh and decodes its JSON output into v
+func (a *Agent) runJSON(ctx context.Context, v interface{}, args ...string) error {
+	if a.debug {
+		fmt.Printf("[github-actions] gh %s\n", strings.Join(args, " "))
+	}
+	out, err := a.run(ctx, args)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(out), v); err != nil {
+		return fmt.Errorf("failed to parse gh %s output: %w", args[0], err)
+	}
+	return nil
+}
+
+// isNotFound reports whether a gh api error is a 404
+func isNotFound(err error) bool {
+	lower := strings.ToLower(err.Error())
+	return strings.Contains(lower, "http 404") || strings.Contains(lower, "not found")
+}
+
+// Repository confirms repo exists and returns its full name and default
+// branch
+func (a *Agent) Repository(ctx context.Context, repo string) (string, string, error) {
+	var raw struct {
+		FullName      string `json:"full_name"`
+		DefaultBranch string `json:"default_branch"`
+		Archived      bool   `json:"archived"`
+	}
+	if err := a.runJSON(ctx, &raw, "api", "repos/"+repo); err != nil {
+		if isNotFound(err) {
+			return "", "", fmt.Errorf("repository %s not found or not visible to the GitHub token", repo)
+		}
+		return "", "", fmt.Errorf("failed to read repository %s: %w", repo, err)
+	}
+	if raw.Archived {
+		return "", "", fmt.Errorf("repository %s is archived; its workflows cannot run", raw.FullName)
+	}
+	return raw.FullName, raw.DefaultBranch, nil
+}
+
+// ConfirmRef checks that ref is a branch or tag of repo and says which
+func (a *Agent) ConfirmRef(ctx context.Context, repo, ref string) (string, error) {
+	var raw json.RawMessage
+	err := a.runJSON(ctx, &raw, "api", fmt.Sprintf("repos/%s/branches/%s", repo, url.PathEscape(ref)))
+	if err == nil {
+		return "branch", nil
+	}
+	if !isNotFound(err) {
+		return "", fmt.Errorf("failed to read branch %s: %w", ref, err)
+	}
+	if err := a.runJSON(ctx, &raw, "api", fmt.Sprintf("repos/%s/git/ref/tags/%s", repo, url.PathEscape(ref))); err == nil {
+		return "tag", nil
+	}
+	return "", fmt.Errorf("%s has no branch or tag named %s", repo, ref)
+}
+
+// Workflow finds the workflow named by file name or display name and
+// reads its workflow_dispatch inputs from the file on ref
+func (a *Agent) Workflow(ctx context.Context, repo, name, ref string) (Workflow, error) {
+	var raw struct {
+		Workflows []struct {
+			ID    int64  `json:"id"`
+			Name  string `json:"name"`
+			Path  string `json:"path"`
+			State string `json:"state"`
+		} `json:"workflows"`
+	}
+	if err := a.runJSON(ctx, &raw, "api", fmt.Sprintf("repos/%s/actions/workflows?per_page=100", repo)); err != nil {
+		return Workflow{}, fmt.Errorf("failed to list workflows: %w", err)
+	}
+
+	var wf Workflow
+	var names []string
+	for _, w := range raw.Workflows {
+		base := path.Base(w.Path)
+		stem := strings.TrimSuffix(strings.TrimSuffix(base, ".yml"), ".yaml")
+		if strings.EqualFold(base, name) || strings.EqualFold(w.Name, name) || strings.EqualFold(stem, name) {
+			wf = Workflow{ID: w.ID, Name: w.Name, Path: w.Path, State: w.State}
+			break
+		}
+		names = append(names, base)
+	}
+	if wf.ID == 0 {
+		sort.Strings(names)
+		return Workflow{}, fmt.Errorf("%s has no workflow named %s (workflows: %s)", repo, name, strings.Join(names, ", "))
+	}
+
+	var content struct {
+		Content  string `json:"content"`
+		Encoding string `json:"encoding"`
+	}
+	if err := a.runJSON(ctx, &content, "api", fmt.Sprintf("repos/%s/contents/%s?ref=%s", repo, wf.Path, url.QueryEscape(ref))); err != nil {
+		if isNotFound(err) {
+			return Workflow{}, fmt.Errorf("%s does not exist on %s", wf.Path, ref)
+		}
+		return Workflow{}, fmt.Errorf("failed to read %s: %w", wf.Path, err)
+	}
+	if content.Encoding != "base64" {
+		return Workflow{}, fmt.Errorf("unsupported encoding %q for %s", content.Encoding, wf.Path)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(content.Content, "\n", ""))
+	if err != nil {
+		return Workflow{}, fmt.Errorf("failed to decode %s: %w", wf.Path, err)
+	}
+	wf.Dispatchable, wf.Inputs, err = dispatchInputs(decoded)
+	if err != nil {
+		return Workflow{}, fmt.Errorf("failed to parse %s: %w", wf.Path, err)
+	}
+	return wf, nil
+}
+
+// dispatchInputs reads whether a workflow file has a workflow_dispatch
+// trigger and the inputs it declares
+func dispatchInputs(content []byte) (bool, map[string]Input, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return false, nil, err
+	}
+	switch v := doc["on"].(type) {
+	case string:
+		return v == "workflow_dispatch", nil, nil
+	case []interface{}:
+		for _, e := range v {
+			if e == "workflow_dispatch" {
+				return true, nil, nil
+			}
+		}
+		return false, nil, nil
+	case map[string]interface{}:
+		trigger, ok := v["workflow_dispatch"]
+		if !ok {
+			return false, nil, nil
+		}
+		spec, _ := trigger.(map[string]interface{})
+		rawInputs, _ := spec["inputs"].(map[string]interface{})
+		inputs := make(map[string]Input, len(rawInputs))
+		for name, raw := range rawInputs {
+			fields, _ := raw.(map[string]interface{})
+			in := Input{}
+			in.Required, _ = fields["required"].(bool)
+			if d, ok := fields["default"]; ok && d != nil {
+				in.Default = fmt.Sprint(d)
+			}
+			if opts, ok := fields["options"].([]interface{}); ok {
+				for _, o := range opts {
+					in.Options = append(in.Options, fmt.Sprint(o))
+				}
+			}
+			inputs[name] = in
+		}
+		return true, inputs, nil
+	}
+	return false, nil, nil
+}
+
+// rawRun is a run as the REST API returns it
+type rawRun struct {
+	ID         int64     `json:"id"`
+	RunNumber  int       `json:"run_number"`
+	RunAttempt int       `json:"run_attempt"`
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Status     string    `json:"status"`
+	Conclusion string    `json:"conclusion"`
+	HeadBranch string    `json:"head_branch"`
+	HeadSHA    string    `json:"head_sha"`
+	Event      string    `json:"event"`
+	HTMLURL    string    `json:"html_url"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (r rawRun) run() Run {
+	return Run{
+		ID: r.ID, Number: r.RunNumber, Attempt: r.RunAttempt, Name: r.Name, Path: r.Path,
+		Status: r.Status, Conclusion: r.Conclusion, HeadBranch: r.HeadBranch, HeadSHA: r.HeadSHA,
+		Event: r.Event, URL: r.HTMLURL, CreatedAt: r.CreatedAt,
+	}
+}
+
+// Run reads one run of repo
+func (a *Agent) Run(ctx context.Context, repo string, id int64) (Run, error) {
+	var raw rawRun
+	if err := a.runJSON(ctx, &raw, "api", fmt.Sprintf("repos/%s/actions/runs/%d", repo, id)); err != nil {
+		if isNotFound(err) {
+			return Run{}, fmt.Errorf("%s has no workflow run %d", repo, id)
+		}
+		return Run{}, fmt.Errorf("failed to read run %d: %w", id, err)
+	}
+	return raw.run(), nil
+}
+
+// LatestRun returns the newest run of repo that match accepts, among runs
+// of workflow on branch when they are set
+func (a *Agent) LatestRun(ctx context.Context, repo, workflow, branch string, match func(Run) bool) (Run, bool, error) {
+	query := url.Values{"per_page": {"50"}}
+	if branch != "" {
+		query.Set("branch", branch)
+	}
+	var raw struct {
+		WorkflowRuns []rawRun `json:"workflow_runs"`
+	}
+	if err := a.runJSON(ctx, &raw, "api", fmt.Sprintf("repos/%s/actions/runs?%s", repo, query.Encode())); err != nil {
+		return Run{}, false, fmt.Errorf("failed to list workflow runs: %w", err)
+	}
+	for _, r := range raw.WorkflowRuns {
+		run := r.run()
+		if workflow != "" && !strings.EqualFold(run.Name, workflow) && !strings.EqualFold(path.Base(run.Path), workflow) &&
+			!strings.EqualFold(strings.TrimSuffix(strings.TrimSuffix(path.Base(run.Path), ".yml"), ".yaml"), workflow) {
+			continue
+		}
+		if match(run) {
+			return run, true, nil
+		}
+	}
+	return Run{}, false, nil
+}
+
+// PendingDeployments lists the environments run waits on for approval
+func (a *Agent) PendingDeployments(ctx context.Context, repo string, runID int64) ([]PendingDeployment, error) {
+	var raw []struct {
+		Environment struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"environment"`
+		WaitTimer             int  `json:"wait_timer"`
+		CurrentUserCanApprove bool `json:"current_user_can_approve"`
+	}
+	if err := a.runJSON(ctx, &raw, "api", fmt.Sprintf("repos/%s/actions/runs/%d/pending_deployments", repo, runID)); err != nil {
+		return nil, fmt.Errorf("failed to read pending deployments of run %d: %w", runID, err)
+	}
+	pending := make([]PendingDeployment, 0, len(raw))
+	for _, p := range raw {
+		pending = append(pending, PendingDeployment{
+			EnvironmentID: p.Environment.ID,
+			Environment:   p.Environment.Name,
+			CanApprove:    p.CurrentUserCanApprove,
+			WaitTimer:     p.WaitTimer,
+		})
+	}
+	return pending, nil
+}
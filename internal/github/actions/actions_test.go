@@ -0,0 +1,220 @@
+package actions
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// fakeGH answers gh api calls from canned JSON keyed by endpoint
+type fakeGH struct {
+	responses map[string]string
+	calls     []string
+}
+
+func (f *fakeGH) run(ctx context.Context, args []string) (string, error) {
+	if len(args) < 2 || args[0] != "api" {
+		return "", errors.New("unexpected gh " + strings.Join(args, " "))
+	}
+	f.calls = append(f.calls, args[1])
+	if out, ok := f.responses[args[1]]; ok {
+		if strings.HasPrefix(out, "ERROR:") {
+			return "", errors.New(out)
+		}
+		return out, nil
+	}
+	return "", errors.New("gh api " + args[1] + " failed: HTTP 404: Not Found")
+}
+
+const deployWorkflow = `name: Deploy
+on:
+  push:
+    branches: [main]
+  workflow_dispatch:
+    inputs:
+      environment:
+        required: true
+        type: choice
+        options: [staging, production]
+      dry_run:
+        type: boolean
+        default: false
+`
+
+func newFakeGH() *fakeGH {
+	return &fakeGH{responses: map[string]string{
+		"repos/acme/shop":                     `{"full_name": "acme/shop", "default_branch": "main"}`,
+		"repos/acme/shop/branches/main":       `{"name": "main"}`,
+		"repos/acme/shop/git/ref/tags/v1.4.0": `{"ref": "refs/tags/v1.4.0"}`,
+		"repos/acme/shop/actions/workflows?per_page=100": `{"workflows": [
+			{"id": 1, "name": "CI", "path": ".github/workflows/ci.yml", "state": "active"},
+			{"id": 2, "name": "Deploy", "path": ".github/workflows/deploy.yml", "state": "active"}]}`,
+		"repos/acme/shop/contents/.github/workflows/deploy.yml?ref=main": `{"encoding": "base64", "content": "` +
+			base64.StdEncoding.EncodeToString([]byte(deployWorkflow)) + `"}`,
+		"repos/acme/shop/contents/.github/workflows/ci.yml?ref=main": `{"encoding": "base64", "content": "` +
+			base64.StdEncoding.EncodeToString([]byte("on: [push, pull_request]\n")) + `"}`,
+		"repos/acme/shop/actions/runs/9876543210": `{"id": 9876543210, "run_number": 412, "run_attempt": 1, "name": "CI",
+			"path": ".github/workflows/ci.yml", "status": "completed", "conclusion": "failure", "head_branch": "main",
+			"head_sha": "4f2a9c1d0e8b7a6f5e4d3c2b1a0f9e8d7c6b5a49"}`,
+		"repos/acme/shop/actions/runs?branch=feature%2Flogin&per_page=50": `{"workflow_runs": [
+			{"id": 501, "run_number": 9, "name": "CI", "path": ".github/workflows/ci.yml", "status": "completed", "conclusion": "success", "head_branch": "feature/login"},
+			{"id": 500, "run_number": 8, "name": "CI", "path": ".github/workflows/ci.yml", "status": "in_progress", "head_branch": "feature/login"}]}`,
+		"repos/acme/shop/actions/runs?per_page=50": `{"workflow_runs": [
+			{"id": 700, "run_number": 31, "name": "Deploy", "path": ".github/workflows/deploy.yml", "status": "waiting", "head_branch": "main"}]}`,
+		"repos/acme/shop/actions/runs/700/pending_deployments": `[
+			{"environment": {"id": 11, "name": "staging"}, "wait_timer": 0, "current_user_can_approve": true},
+			{"environment": {"id": 12, "name": "production"}, "wait_timer": 15, "current_user_can_approve": true},
+			{"environment": {"id": 13, "name": "audit"}, "current_user_can_approve": false}]`,
+	}}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     Request
+	}{
+		{
+			"trigger deploy.yml on main with environment=production dry_run=true",
+			Request{Op: Dispatch, Workflow: "deploy.yml", Ref: "main", Inputs: map[string]string{"environment": "production", "dry_run": "true"}},
+		},
+		{
+			"run the Release workflow in acme/shop on tag v1.4.0",
+			Request{Op: Dispatch, Repo: "acme/shop", Workflow: "Release", Ref: "v1.4.0"},
+		},
+		{
+			"re-run the failed jobs of run 9876543210",
+			Request{Op: Rerun, RunID: 9876543210},
+		},
+		{
+			"rerun all jobs of https://github.com/acme/shop/actions/runs/9876543210",
+			Request{Op: Rerun, Repo: "acme/shop", RunID: 9876543210, AllJobs: true},
+		},
+		{
+			"cancel the running ci workflow on feature/login",
+			Request{Op: Cancel, Workflow: "ci", Ref: "feature/login"},
+		},
+		{
+			`approve the pending deployment to production for run 9876543210 with comment "smoke tests passed"`,
+			Request{Op: Approve, RunID: 9876543210, Environments: []string{"production"}, Comment: "smoke tests passed"},
+		},
+	}
+	for _, tt := range tests {
+		if got := ParseRequest(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRequest(%q) =\n  %+v\nwant\n  %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestDispatchPlan(t *testing.T) {
+	gh := newFakeGH()
+	agent := NewAgent(gh.run, false)
+
+	req := ParseRequest("trigger deploy.yml with environment=staging")
+	plan, err := agent.Plan(context.Background(), req, "acme/shop", "q", testNow)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	want := []string{"gh", "workflow", "run", "deploy.yml", "--repo", "acme/shop", "--ref", "main", "-f", "environment=staging"}
+	if plan.Provider != "github" || len(plan.Commands) != 1 || !reflect.DeepEqual(plan.Commands[0].Args, want) {
+		t.Fatalf("plan = %s %+v", plan.Provider, plan.Commands)
+	}
+	if plan.Summary != "Trigger Deploy (deploy.yml) on branch main of acme/shop with environment=staging" {
+		t.Errorf("summary = %q", plan.Summary)
+	}
+	if !strings.Contains(plan.Notes[0], "repository acme/shop, branch main") || !strings.Contains(strings.Join(plan.Notes, "\n"), `dry_run is not set and takes its default "false"`) {
+		t.Errorf("notes = %v", plan.Notes)
+	}
+
+	for question, wantErr := range map[string]string{
+		"trigger deploy.yml":                                  "requires input environment",
+		"trigger deploy.yml with environment=qa":              "must be one of staging, production",
+		"trigger deploy.yml with region=eu":                   `takes no input "region"`,
+		"trigger ci.yml":                                      "no workflow_dispatch trigger",
+		"trigger nightly.yml":                                 "no workflow named nightly.yml (workflows: ci.yml, deploy.yml)",
+		"trigger deploy.yml on release/9 with environment=qa": "no branch or tag named release/9",
+	} {
+		_, err := agent.Plan(context.Background(), ParseRequest(question), "acme/shop", question, testNow)
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Plan(%q) error = %v, want %q", question, err, wantErr)
+		}
+	}
+}
+
+func TestRunPlans(t *testing.T) {
+	gh := newFakeGH()
+	agent := NewAgent(gh.run, false)
+	ctx := context.Background()
+
+	plan, err := agent.Plan(ctx, ParseRequest("re-run the failed jobs of run 9876543210"), "acme/shop", "q", testNow)
+	if err != nil {
+		t.Fatalf("rerun: %v", err)
+	}
+	if want := []string{"gh", "run", "rerun", "9876543210", "--failed", "--repo", "acme/shop"}; !reflect.DeepEqual(plan.Commands[0].Args, want) {
+		t.Errorf("rerun args = %v", plan.Commands[0].Args)
+	}
+	if plan.Summary != "Re-run failed jobs of CI #412 (run 9876543210) on branch main of acme/shop" {
+		t.Errorf("rerun summary = %q", plan.Summary)
+	}
+	if _, err := agent.Plan(ctx, ParseRequest("cancel run 9876543210"), "acme/shop", "q", testNow); err == nil || !strings.Contains(err.Error(), "run 9876543210 is failure; only queued or running runs can be cancelled") {
+		t.Errorf("cancel finished run error = %v", err)
+	}
+
+	plan, err = agent.Plan(ctx, ParseRequest("cancel the running ci workflow on feature/login"), "acme/shop", "q", testNow)
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if want := []string{"gh", "run", "cancel", "500", "--repo", "acme/shop"}; !reflect.DeepEqual(plan.Commands[0].Args, want) {
+		t.Errorf("cancel args = %v, want the in-progress run", plan.Commands[0].Args)
+	}
+
+	plan, err = agent.Plan(ctx, ParseRequest("approve the pending deployment to production"), "acme/shop", "q", testNow)
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	want := []string{"gh", "api", "--method", "POST", "repos/acme/shop/actions/runs/700/pending_deployments",
+		"-F", "environment_ids[]=12", "-f", "state=approved", "-f", "comment=" + DefaultApprovalComment}
+	if !reflect.DeepEqual(plan.Commands[0].Args, want) {
+		t.Errorf("approve args = %v", plan.Commands[0].Args)
+	}
+	if notes := strings.Join(plan.Notes, "\n"); !strings.Contains(notes, "2 other environments stay pending") || !strings.Contains(notes, "15 minute wait timer") {
+		t.Errorf("approve notes = %v", plan.Notes)
+	}
+
+	plan, err = agent.Plan(ctx, Request{Op: Approve}, "acme/shop", "q", testNow)
+	if err != nil {
+		t.Fatalf("approve all: %v", err)
+	}
+	if got := strings.Join(plan.Commands[0].Args, " "); !strings.Contains(got, "environment_ids[]=11 -F environment_ids[]=12 -f") {
+		t.Errorf("approve all skips environments the user cannot approve, got %s", got)
+	}
+	if _, err := agent.Plan(ctx, Request{Op: Approve, Environments: []string{"audit"}}, "acme/shop", "q", testNow); err == nil || !strings.Contains(err.Error(), "not a required reviewer for environment audit") {
+		t.Errorf("approve audit error = %v", err)
+	}
+}
+
+func TestIsActionQuestion(t *testing.T) {
+	for _, q := range []string{
+		"trigger the deploy workflow on main",
+		"retry failed jobs for https://github.com/acme/shop/actions/runs/9876543210",
+		"stop the running release workflow",
+	} {
+		if !IsActionQuestion(q) {
+			t.Errorf("IsActionQuestion(%q) = false", q)
+		}
+	}
+	for _, q := range []string{
+		"list workflow runs",
+		"write a workflow that deploys on push",
+		"trace 500 errors in cloud run",
+	} {
+		if IsActionQuestion(q) {
+			t.Errorf("IsActionQuestion(%q) = true", q)
+		}
+	}
+}
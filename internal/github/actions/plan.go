@@ -0,0 +1,355 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// DefaultApprovalComment is the review comment approvals leave when the
+// question gives none
+const DefaultApprovalComment = "Approved via clanker"
+
+// failedConclusions are the conclusions of runs whose failed jobs can be
+// re-run
+var failedConclusions = []string{"failure", "cancelled", "timed_out", "startup_failure"}
+
+// activeStatuses are the statuses of runs that can still be cancelled
+var activeStatuses = []string{"queued", "in_progress", "waiting", "requested", "pending"}
+
+// Plan confirms the repository, ref, workflow and run req names and
+// builds the maker plan for the change. repo is used when req names no
+// repository. The plan is returned for review; nothing is changed.
+func (a *Agent) Plan(ctx context.Context, req Request, repo, question string, now time.Time) (*maker.Plan, error) {
+	if req.Repo != "" {
+		repo = req.Repo
+	}
+	if repo == "" {
+		return nil, fmt.Errorf("name the repository, e.g. \"in acme/shop\", or set github.owner and github.repo")
+	}
+	fullName, defaultBranch, err := a.Repository(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Op {
+	case Dispatch:
+		return a.dispatchPlan(ctx, req, fullName, defaultBranch, question, now)
+	case Rerun:
+		match := func(r Run) bool { return r.Status == "completed" && slices.Contains(failedConclusions, r.Conclusion) }
+		want := "failed"
+		if req.AllJobs {
+			match = func(r Run) bool { return r.Status == "completed" }
+			want = "finished"
+		}
+		run, err := a.pickRun(ctx, req, fullName, want, match)
+		if err != nil {
+			return nil, err
+		}
+		return RerunPlan(fullName, run, req.AllJobs, question, now), nil
+	case Cancel:
+		run, err := a.pickRun(ctx, req, fullName, "queued or running", func(r Run) bool { return slices.Contains(activeStatuses, r.Status) })
+		if err != nil {
+			return nil, err
+		}
+		return CancelPlan(fullName, run, question, now), nil
+	case Approve:
+		run, err := a.pickRun(ctx, req, fullName, "waiting", func(r Run) bool { return r.Status == "waiting" })
+		if err != nil {
+			return nil, err
+		}
+		pending, err := a.PendingDeployments(ctx, fullName, run.ID)
+		if err != nil {
+			return nil, err
+		}
+		return ApprovePlan(fullName, run, pending, req.Environments, req.Comment, question, now)
+	}
+	return nil, fmt.Errorf("say what to do: trigger a workflow, or re-run, cancel or approve a run")
+}
+
+// pickRun reads the run req names, or finds the newest run match accepts,
+// and checks it is in a state the change applies to. want describes that
+// state in errors.
+func (a *Agent) pickRun(ctx context.Context, req Request, repo, want string, match func(Run) bool) (Run, error) {
+	if req.RunID != 0 {
+		run, err := a.Run(ctx, repo, req.RunID)
+		if err != nil {
+			return Run{}, err
+		}
+		if !match(run) {
+			return Run{}, fmt.Errorf("run %d is %s; only %s runs can be %s", run.ID, runState(run), want, pastTense(req.Op))
+		}
+		return run, nil
+	}
+	run, ok, err := a.LatestRun(ctx, repo, req.Workflow, req.Ref, match)
+	if err != nil {
+		return Run{}, err
+	}
+	if !ok {
+		scope := repo
+		if req.Workflow != "" {
+			scope += " for workflow " + req.Workflow
+		}
+		if req.Ref != "" {
+			scope += " on " + req.Ref
+		}
+		return Run{}, fmt.Errorf("no %s run in %s; name the run ID, e.g. \"run 123456789\"", want, scope)
+	}
+	return run, nil
+}
+
+// dispatchPlan confirms the ref and the workflow's dispatch inputs and
+// returns the plan that triggers it
+func (a *Agent) dispatchPlan(ctx context.Context, req Request, repo, defaultBranch, question string, now time.Time) (*maker.Plan, error) {
+	if req.Workflow == "" {
+		return nil, fmt.Errorf("name the workflow, e.g. \"trigger deploy.yml on main\"")
+	}
+	ref := req.Ref
+	if ref == "" {
+		ref = defaultBranch
+	}
+	kind, err := a.ConfirmRef(ctx, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	wf, err := a.Workflow(ctx, repo, req.Workflow, ref)
+	if err != nil {
+		return nil, err
+	}
+	return DispatchPlan(repo, kind, ref, wf, req.Inputs, question, now)
+}
+
+// DispatchPlan returns the plan that triggers wf on ref of repo with
+// inputs, after checking the workflow can be dispatched and takes those
+// inputs. kind is "branch" or "tag".
+func DispatchPlan(repo, kind, ref string, wf Workflow, inputs map[string]string, question string, now time.Time) (*maker.Plan, error) {
+	if wf.State != "" && wf.State != "active" {
+		return nil, fmt.Errorf("workflow %s is %s; enable it before dispatching", wf.File(), strings.ReplaceAll(wf.State, "_", " "))
+	}
+	if !wf.Dispatchable {
+		return nil, fmt.Errorf("%s has no workflow_dispatch trigger on %s, so it cannot be run by hand", wf.File(), ref)
+	}
+
+	var notes []string
+	for name, value := range inputs {
+		in, ok := wf.Inputs[name]
+		if !ok {
+			return nil, fmt.Errorf("%s takes no input %q (inputs: %s)", wf.File(), name, inputNames(wf.Inputs))
+		}
+		if len(in.Options) > 0 && !slices.Contains(in.Options, value) {
+			return nil, fmt.Errorf("input %s must be one of %s, not %q", name, strings.Join(in.Options, ", "), value)
+		}
+		if hasShellOperator(value) {
+			return nil, fmt.Errorf("input %s contains characters a plan cannot carry (; | && or a newline)", name)
+		}
+	}
+	for _, name := range inputNamesSorted(wf.Inputs) {
+		in := wf.Inputs[name]
+		if _, ok := inputs[name]; ok {
+			continue
+		}
+		if in.Required && in.Default == "" {
+			return nil, fmt.Errorf("%s requires input %s, e.g. \"with %s=...\"", wf.File(), name, name)
+		}
+		if in.Default != "" {
+			notes = append(notes, fmt.Sprintf("Input %s is not set and takes its default %q.", name, in.Default))
+		}
+	}
+
+	args := []string{"gh", "workflow", "run", wf.File(), "--repo", repo, "--ref", ref}
+	pairs := sortedInputs(inputs)
+	for _, p := range pairs {
+		args = append(args, "-f", p)
+	}
+
+	summary := fmt.Sprintf("Trigger %s (%s) on %s %s of %s", wf.Name, wf.File(), kind, ref, repo)
+	if len(pairs) > 0 {
+		summary += " with " + strings.Join(pairs, ", ")
+	}
+	plan := newPlan(question, now)
+	plan.Summary = summary
+	plan.Commands = []maker.Command{{
+		Args:   args,
+		Reason: fmt.Sprintf("Dispatch %s on %s", wf.File(), ref),
+	}}
+	plan.Notes = append([]string{confirmedNote(repo, kind, ref)}, notes...)
+	return plan, nil
+}
+
+// RerunPlan returns the plan that re-runs run's failed jobs, or all of its
+// jobs
+func RerunPlan(repo string, run Run, allJobs bool, question string, now time.Time) *maker.Plan {
+	args := []string{"gh", "run", "rerun", strconv.FormatInt(run.ID, 10)}
+	what := "failed jobs of"
+	if allJobs {
+		what = "every job of"
+	} else {
+		args = append(args, "--failed")
+	}
+	args = append(args, "--repo", repo)
+
+	plan := newPlan(question, now)
+	plan.Summary = fmt.Sprintf("Re-run %s %s on branch %s of %s", what, run.Label(), run.HeadBranch, repo)
+	plan.Commands = []maker.Command{{
+		Args:   args,
+		Reason: fmt.Sprintf("Re-run %s run %d, which is %s after attempt %d", what, run.ID, runState(run), max(run.Attempt, 1)),
+	}}
+	plan.Notes = []string{confirmedNote(repo, "branch", run.HeadBranch)}
+	if run.HeadSHA != "" {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("The re-run uses the run's original commit %s and workflow file, not the branch's latest.", shortSHA(run.HeadSHA)))
+	}
+	return plan
+}
+
+// CancelPlan returns the plan that cancels run
+func CancelPlan(repo string, run Run, question string, now time.Time) *maker.Plan {
+	plan := newPlan(question, now)
+	plan.Summary = fmt.Sprintf("Cancel %s on branch %s of %s", run.Label(), run.HeadBranch, repo)
+	plan.Commands = []maker.Command{{
+		Args:   []string{"gh", "run", "cancel", strconv.FormatInt(run.ID, 10), "--repo", repo},
+		Reason: fmt.Sprintf("Cancel run %d, which is %s", run.ID, runState(run)),
+	}}
+	plan.Notes = []string{
+		confirmedNote(repo, "branch", run.HeadBranch),
+		"Jobs already running stop at their next step; steps with if: always() or cancelled() still run.",
+	}
+	return plan
+}
+
+// ApprovePlan returns the plan that approves run's pending deployments to
+// environments, or to every pending environment the user can approve when
+// environments is empty
+func ApprovePlan(repo string, run Run, pending []PendingDeployment, environments []string, comment, question string, now time.Time) (*maker.Plan, error) {
+	if len(pending) == 0 {
+		return nil, fmt.Errorf("run %d has no pending deployments", run.ID)
+	}
+	var names []string
+	for _, p := range pending {
+		names = append(names, p.Environment)
+	}
+
+	var approve []PendingDeployment
+	if len(environments) > 0 {
+		for _, env := range environments {
+			i := slices.IndexFunc(pending, func(p PendingDeployment) bool { return strings.EqualFold(p.Environment, env) })
+			if i < 0 {
+				return nil, fmt.Errorf("run %d is not waiting on environment %s (pending: %s)", run.ID, env, strings.Join(names, ", "))
+			}
+			if !pending[i].CanApprove {
+				return nil, fmt.Errorf("you are not a required reviewer for environment %s", pending[i].Environment)
+			}
+			approve = append(approve, pending[i])
+		}
+	} else {
+		for _, p := range pending {
+			if p.CanApprove {
+				approve = append(approve, p)
+			}
+		}
+		if len(approve) == 0 {
+			return nil, fmt.Errorf("you are not a required reviewer for any environment run %d waits on (%s)", run.ID, strings.Join(names, ", "))
+		}
+	}
+
+	if comment == "" {
+		comment = DefaultApprovalComment
+	}
+	if hasShellOperator(comment) {
+		return nil, fmt.Errorf("the comment contains characters a plan cannot carry (; | && or a newline)")
+	}
+
+	args := []string{"gh", "api", "--method", "POST", fmt.Sprintf("repos/%s/actions/runs/%d/pending_deployments", repo, run.ID)}
+	var envNames []string
+	for _, p := range approve {
+		args = append(args, "-F", fmt.Sprintf("environment_ids[]=%d", p.EnvironmentID))
+		envNames = append(envNames, p.Environment)
+	}
+	args = append(args, "-f", "state=approved", "-f", "comment="+comment)
+
+	plan := newPlan(question, now)
+	plan.Summary = fmt.Sprintf("Approve deployment of %s on branch %s of %s to %s", run.Label(), run.HeadBranch, repo, strings.Join(envNames, ", "))
+	plan.Commands = []maker.Command{{
+		Args:   args,
+		Reason: fmt.Sprintf("Approve the pending deployment to %s with comment %q", strings.Join(envNames, ", "), comment),
+	}}
+	plan.Notes = []string{confirmedNote(repo, "branch", run.HeadBranch)}
+	if len(approve) < len(pending) {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("%d other environments stay pending.", len(pending)-len(approve)))
+	}
+	for _, p := range approve {
+		if p.WaitTimer > 0 {
+			plan.Notes = append(plan.Notes, fmt.Sprintf("%s has a %d minute wait timer that starts after approval.", p.Environment, p.WaitTimer))
+		}
+	}
+	return plan, nil
+}
+
+func newPlan(question string, now time.Time) *maker.Plan {
+	return &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "github",
+		Question:  question,
+	}
+}
+
+// confirmedNote records the repository and ref the plan was checked
+// against; every step pins --repo so applying it from another checkout
+// acts on the same repository
+func confirmedNote(repo, kind, ref string) string {
+	return fmt.Sprintf("Confirmed against GitHub when planned: repository %s, %s %s. Every step names the repository, so the plan acts on it wherever it is applied.", repo, kind, ref)
+}
+
+// runState describes a run's status, with its conclusion once finished
+func runState(r Run) string {
+	if r.Status == "completed" && r.Conclusion != "" {
+		return strings.ReplaceAll(r.Conclusion, "_", " ")
+	}
+	return strings.ReplaceAll(r.Status, "_", " ")
+}
+
+func pastTense(op Operation) string {
+	switch op {
+	case Rerun:
+		return "re-run"
+	case Cancel:
+		return "cancelled"
+	case Approve:
+		return "approved"
+	}
+	return "changed"
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+func inputNamesSorted(inputs map[string]Input) []string {
+	names := make([]string, 0, len(inputs))
+	for name := range inputs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func inputNames(inputs map[string]Input) string {
+	if len(inputs) == 0 {
+		return "none"
+	}
+	return strings.Join(inputNamesSorted(inputs), ", ")
+}
+
+// hasShellOperator reports whether s has a character the maker executor
+// refuses in plan arguments
+func hasShellOperator(s string) bool {
+	return strings.ContainsAny(s, ";|\n\r") || strings.Contains(s, "&&")
+}
@@ -0,0 +1,232 @@
+package actions
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operation is what a question asks of GitHub Actions
+type Operation int
+
+const (
+	// Dispatch triggers a workflow_dispatch run of a workflow
+	Dispatch Operation = iota + 1
+	// Rerun re-runs a finished run, only its failed jobs unless asked
+	// otherwise
+	Rerun
+	// Cancel cancels a queued or running run
+	Cancel
+	// Approve approves a run's pending deployments
+	Approve
+)
+
+// String is the operation's name in plan summaries and errors
+func (o Operation) String() string {
+	switch o {
+	case Dispatch:
+		return "dispatch"
+	case Rerun:
+		return "re-run"
+	case Cancel:
+		return "cancel"
+	case Approve:
+		return "approve"
+	}
+	return "unknown"
+}
+
+// Request is a question translated into a GitHub Actions mutation
+type Request struct {
+	Op Operation
+	// Repo is owner/name when the question names a repository; the
+	// configured or current repository otherwise
+	Repo string
+	// Workflow is a workflow file name such as deploy.yml or a workflow
+	// name such as Deploy
+	Workflow string
+	// Ref is the branch or tag to dispatch on, or the branch whose latest
+	// run a re-run, cancel or approval targets
+	Ref string
+	// Inputs are workflow_dispatch inputs
+	Inputs map[string]string
+	// RunID picks the run; the latest matching run when zero
+	RunID int64
+	// AllJobs re-runs every job of a run instead of only the failed ones
+	AllJobs bool
+	// Environments limits an approval to these environments; every
+	// pending environment the user can approve when empty
+	Environments []string
+	Comment      string
+}
+
+var (
+	dispatchRe     = regexp.MustCompile(`(?i)\b(?:trigger|dispatch|kick off|start|run)\b`)
+	rerunRe        = regexp.MustCompile(`(?i)\b(?:re-?run|retry|restart)\b`)
+	cancelRe       = regexp.MustCompile(`(?i)\b(?:cancel|abort|stop)\b`)
+	approveRe      = regexp.MustCompile(`(?i)\bapprove\b`)
+	workflowWordRe = regexp.MustCompile(`(?i)\b(?:workflows?|github actions?|[\w.-]+\.ya?ml)\b`)
+	actionsRe      = regexp.MustCompile(`(?i)\b(?:workflows?|github actions?|runs?|jobs?|[\w.-]+\.ya?ml)\b`)
+	deployRe       = regexp.MustCompile(`(?i)\b(?:deployments?|deploys?|environments?)\b`)
+	// notActionRe turns away questions about writing or reading workflows
+	// rather than acting on them
+	notActionRe = regexp.MustCompile(`(?i)\b(?:how (?:do|can|to)|why|explain|write|create|set ?up|add|generate|lambda|ssm|ec2|kubectl|pods?|terraform|instances?|cloud run|cloud build|codebuild|codepipeline)\b`)
+
+	runURLRe   = regexp.MustCompile(`github\.com/([\w.-]+/[\w.-]+)/actions/runs/(\d+)`)
+	runIDRe    = regexp.MustCompile(`(?i)\brun\s+(?:id\s+)?#?(\d{4,})\b|#(\d{4,})\b`)
+	repoRe     = regexp.MustCompile(`(?i)\b(?:repo(?:sitory)?|in|for|of)\s+["'` + "`" + `]?([\w.-]+/[\w.-]+)\b`)
+	fileRe     = regexp.MustCompile(`(?i)\b([\w.-]+\.ya?ml)\b`)
+	workflowRe = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bworkflow\s+(?:named\s+|called\s+)?["'` + "`" + `]([^"'` + "`" + `]+)["'` + "`" + `]`),
+		regexp.MustCompile(`(?i)\b([\w-]+)\s+(?:workflow|pipeline)\b`),
+		regexp.MustCompile(`(?i)\bworkflow\s+(?:named\s+|called\s+)?([\w-]+)`),
+	}
+	refRe     = regexp.MustCompile(`(?i)\b(?:on|against|from|ref|branch|tag)\s+(?:the\s+)?(?:branch\s+|tag\s+|ref\s+)?["'` + "`" + `]?([\w][\w./-]*)`)
+	inputRe   = regexp.MustCompile(`([A-Za-z_][\w-]*)=("[^"]*"|'[^']*'|[^\s,]+)`)
+	envRe     = regexp.MustCompile(`(?i)\b(?:to|for|in)\s+(?:the\s+)?["'` + "`" + `]?([\w.-]+)["'` + "`" + `]?\s+(?:environment|env)\b|\b(?:environment|env)\s+["'` + "`" + `]?([\w.-]+)|\bdeploy(?:ment)?s?\s+(?:to|for|in)\s+(?:the\s+)?["'` + "`" + `]?([\w.-]+)`)
+	commentRe = regexp.MustCompile(`(?i)\b(?:comment|message|note)\s+["'` + "`" + `]([^"'` + "`" + `]+)["'` + "`" + `]`)
+	allJobsRe = regexp.MustCompile(`(?i)\b(?:all|every|entire|whole|full)\s+(?:the\s+)?(?:jobs?|run)\b`)
+)
+
+// notNames are words the workflow and ref patterns can land on that never
+// name a workflow or branch
+var notNames = map[string]bool{
+	"a": true, "an": true, "the": true, "my": true, "our": true, "this": true, "that": true, "latest": true, "last": true,
+	"failed": true, "failing": true, "running": true, "pending": true, "waiting": true, "current": true, "github": true,
+	"actions": true, "workflow": true, "workflows": true, "run": true, "runs": true, "job": true, "jobs": true,
+	"dispatch": true, "trigger": true, "cancel": true, "approve": true, "rerun": true, "re-run": true, "retry": true,
+	"start": true, "with": true, "for": true, "to": true, "in": true, "on": true, "of": true, "and": true, "repo": true,
+	"repository": true, "branch": true, "tag": true, "ref": true, "deployment": true, "deployments": true, "it": true,
+	"same": true, "new": true, "environment": true, "env": true, "all": true, "every": true, "whole": true, "entire": true,
+	"full": true, "inputs": true, "input": true,
+}
+
+// IsActionQuestion reports whether question asks to trigger, re-run,
+// cancel or approve a GitHub Actions run, rather than to read or write
+// workflows
+func IsActionQuestion(question string) bool {
+	if notActionRe.MatchString(question) {
+		return false
+	}
+	op := operation(question)
+	if op == 0 {
+		return false
+	}
+	if runURLRe.MatchString(question) {
+		return true
+	}
+	switch op {
+	case Dispatch:
+		// "run" is also the verb, so only a workflow names what to start
+		return workflowWordRe.MatchString(question)
+	case Approve:
+		return deployRe.MatchString(question) && actionsRe.MatchString(question) || strings.Contains(strings.ToLower(question), "pending deployment")
+	}
+	return actionsRe.MatchString(question)
+}
+
+// operation picks the mutation a question asks for. Approving and
+// re-running are checked first because their questions also mention
+// deploying or running.
+func operation(question string) Operation {
+	switch {
+	case approveRe.MatchString(question):
+		return Approve
+	case rerunRe.MatchString(question):
+		return Rerun
+	case cancelRe.MatchString(question):
+		return Cancel
+	case dispatchRe.MatchString(question):
+		return Dispatch
+	}
+	return 0
+}
+
+// ParseRequest reads a GitHub Actions mutation from question
+func ParseRequest(question string) Request {
+	req := Request{Op: operation(question)}
+	rest := question
+
+	if m := commentRe.FindStringSubmatch(rest); m != nil {
+		req.Comment = strings.TrimSpace(m[1])
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+	if m := runURLRe.FindStringSubmatch(rest); m != nil {
+		req.Repo = m[1]
+		req.RunID, _ = strconv.ParseInt(m[2], 10, 64)
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+	if m := repoRe.FindStringSubmatch(rest); m != nil && req.Repo == "" {
+		req.Repo = strings.TrimSuffix(m[1], ".git")
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+	if m := runIDRe.FindStringSubmatch(rest); m != nil && req.RunID == 0 {
+		id := m[1]
+		if id == "" {
+			id = m[2]
+		}
+		req.RunID, _ = strconv.ParseInt(id, 10, 64)
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+
+	if req.Op == Dispatch {
+		for _, m := range inputRe.FindAllStringSubmatch(rest, -1) {
+			if req.Inputs == nil {
+				req.Inputs = map[string]string{}
+			}
+			req.Inputs[m[1]] = strings.Trim(m[2], `"'`)
+			rest = strings.Replace(rest, m[0], " ", 1)
+		}
+	}
+	if req.Op == Approve {
+		for _, m := range envRe.FindAllStringSubmatch(rest, -1) {
+			env := m[1] + m[2] + m[3]
+			if !notNames[strings.ToLower(env)] {
+				req.Environments = append(req.Environments, env)
+			}
+			rest = strings.Replace(rest, m[0], " ", 1)
+		}
+	}
+
+	if m := fileRe.FindStringSubmatch(rest); m != nil {
+		req.Workflow = m[1]
+		rest = strings.Replace(rest, m[0], " ", 1)
+	} else {
+		req.Workflow = parseWorkflow(rest)
+	}
+	for _, m := range refRe.FindAllStringSubmatch(rest, -1) {
+		ref := strings.TrimRight(m[1], "./")
+		if ref != "" && !notNames[strings.ToLower(ref)] && !strings.EqualFold(ref, req.Workflow) {
+			req.Ref = ref
+			break
+		}
+	}
+	req.AllJobs = req.Op == Rerun && allJobsRe.MatchString(question)
+	return req
+}
+
+// parseWorkflow returns the first word the workflow patterns find that
+// could name a workflow
+func parseWorkflow(question string) string {
+	for _, re := range workflowRe {
+		for _, m := range re.FindAllStringSubmatch(question, -1) {
+			name := strings.TrimSpace(m[1])
+			if name == "" || notNames[strings.ToLower(name)] {
+				continue
+			}
+			return name
+		}
+	}
+	return ""
+}
+
+// sortedInputs returns inputs as name=value pairs in name order
+func sortedInputs(inputs map[string]string) []string {
+	pairs := make([]string, 0, len(inputs))
+	for k, v := range inputs {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
@@ -169,6 +169,12 @@ func runGitHubCLIWithRetry(ctx context.Context, args ...string) (string, error)
 	return "", lastErr
 }
 
+// ExecCLI runs a gh CLI command, retrying GitHub's transient gateway
+// errors
+func (c *Client) ExecCLI(ctx context.Context, args []string) (string, error) {
+	return runGitHubCLIWithRetry(ctx, args...)
+}
+
 func hasUsableCopilotCLI(ctx context.Context) bool {
 	if _, err := exec.LookPath("copilot"); err != nil {
 		return false
@@ -622,6 +628,13 @@ func selectWorkflowRunsForDetail(runs []workflowRunSummary, limit int) []workflo
 }
 
 func (c *Client) GetRelevantContext(ctx context.Context, question string) (string, error) {
+	return c.relevantContext(ctx, question, true)
+}
+
+// relevantContext gathers the repository's context for question. The
+// viewer's repository list is only included with includeRepoList, since a
+// multi-repository lookup would repeat it in every section.
+func (c *Client) relevantContext(ctx context.Context, question string, includeRepoList bool) (string, error) {
 	owner, repo, err := c.ResolveRepository(ctx)
 	if err != nil {
 		return "", err
@@ -639,7 +652,7 @@ func (c *Client) GetRelevantContext(ctx context.Context, question string) (strin
 		"pull_request_target", "repository_dispatch", "workflow_dispatch", "schedule", "trigger", "triggers",
 	)
 
-	if strings.Contains(questionLower, "repo") || strings.Contains(questionLower, "repository") {
+	if includeRepoList && (strings.Contains(questionLower, "repo") || strings.Contains(questionLower, "repository")) {
 		repos, err := c.ListRepositories(ctx, 25)
 		if err == nil {
 			contextText.WriteString("GitHub Repositories:\n")
@@ -0,0 +1,102 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v56/github"
+)
+
+// FilesPullRequest describes files to commit on a new branch and the pull
+// request that proposes them
+type FilesPullRequest struct {
+	Base    string // target branch; the repository default when empty
+	Branch  string
+	Title   string
+	Body    string
+	Message string            // commit message; Title when empty
+	Files   map[string]string // repository path -> content
+}
+
+// CreateFilesPullRequest commits the files in one commit on a new branch
+// cut from the base branch and opens a pull request. It returns the pull
+// request URL.
+func (c *Client) CreateFilesPullRequest(ctx context.Context, pr FilesPullRequest) (string, error) {
+	if _, _, err := c.ResolveRepository(ctx); err != nil {
+		return "", err
+	}
+	if len(pr.Files) == 0 {
+		return "", fmt.Errorf("no files to commit")
+	}
+
+	base := strings.TrimSpace(pr.Base)
+	if base == "" {
+		repo, _, err := c.client.Repositories.Get(ctx, c.owner, c.repo)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up %s/%s: %w", c.owner, c.repo, err)
+		}
+		base = repo.GetDefaultBranch()
+	}
+
+	baseRef, _, err := c.client.Git.GetRef(ctx, c.owner, c.repo, "refs/heads/"+base)
+	if err != nil {
+		return "", fmt.Errorf("failed to read branch %s: %w", base, err)
+	}
+	parentSHA := baseRef.GetObject().GetSHA()
+	parent, _, err := c.client.Git.GetCommit(ctx, c.owner, c.repo, parentSHA)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit %s: %w", parentSHA, err)
+	}
+
+	paths := make([]string, 0, len(pr.Files))
+	for path := range pr.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	entries := make([]*github.TreeEntry, 0, len(paths))
+	for _, path := range paths {
+		entries = append(entries, &github.TreeEntry{
+			Path:    github.String(path),
+			Mode:    github.String("100644"),
+			Type:    github.String("blob"),
+			Content: github.String(pr.Files[path]),
+		})
+	}
+	tree, _, err := c.client.Git.CreateTree(ctx, c.owner, c.repo, parent.GetTree().GetSHA(), entries)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tree: %w", err)
+	}
+
+	message := pr.Message
+	if message == "" {
+		message = pr.Title
+	}
+	commit, _, err := c.client.Git.CreateCommit(ctx, c.owner, c.repo, &github.Commit{
+		Message: github.String(message),
+		Tree:    tree,
+		Parents: []*github.Commit{{SHA: github.String(parentSHA)}},
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	if _, _, err := c.client.Git.CreateRef(ctx, c.owner, c.repo, &github.Reference{
+		Ref:    github.String("refs/heads/" + pr.Branch),
+		Object: &github.GitObject{SHA: commit.SHA},
+	}); err != nil {
+		return "", fmt.Errorf("failed to create branch %s: %w", pr.Branch, err)
+	}
+
+	created, _, err := c.client.PullRequests.Create(ctx, c.owner, c.repo, &github.NewPullRequest{
+		Title: github.String(pr.Title),
+		Head:  github.String(pr.Branch),
+		Base:  github.String(base),
+		Body:  github.String(pr.Body),
+	})
+	if err != nil {
+		return "", fmt.Errorf("branch %s was pushed but the pull request failed: %w", pr.Branch, err)
+	}
+	return created.GetHTMLURL(), nil
+}
@@ -0,0 +1,330 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultRepoConcurrency is how many repositories are queried at once when
+// github.concurrency is not set
+const DefaultRepoConcurrency = 4
+
+// DefaultMaxRepos caps how many repositories an owner/* entry expands to
+// when github.max_repos is not set
+const DefaultMaxRepos = 50
+
+// callsPerRepo is a high estimate of the API calls one repository's context
+// takes. A batch starts only while the rate limit leaves room for it.
+const callsPerRepo = 25
+
+// maxRateLimitWait is the longest a batch waits for the rate limit to reset
+// before the remaining repositories are skipped
+const maxRateLimitWait = 30 * time.Second
+
+// ConfiguredRepos returns github.repos, trimmed and without duplicates.
+// Entries are owner/name, or owner/* for every repository of an owner.
+func ConfiguredRepos() []string {
+	return NormalizeRepos(viper.GetStringSlice("github.repos"))
+}
+
+// NormalizeRepos trims entries, drops duplicates and anything that is not
+// owner/name or owner/*, and splits comma-separated values
+func NormalizeRepos(entries []string) []string {
+	seen := map[string]bool{}
+	var repos []string
+	for _, entry := range entries {
+		for _, value := range strings.Split(entry, ",") {
+			owner, name, ok := strings.Cut(strings.TrimSpace(value), "/")
+			owner, name = strings.TrimSpace(owner), strings.TrimSuffix(strings.TrimSpace(name), ".git")
+			if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+				continue
+			}
+			full := owner + "/" + name
+			if seen[strings.ToLower(full)] {
+				continue
+			}
+			seen[strings.ToLower(full)] = true
+			repos = append(repos, full)
+		}
+	}
+	return repos
+}
+
+// RepoConcurrency returns github.concurrency, or DefaultRepoConcurrency
+func RepoConcurrency() int {
+	if n := viper.GetInt("github.concurrency"); n > 0 {
+		return n
+	}
+	return DefaultRepoConcurrency
+}
+
+// MaxRepos returns github.max_repos, or DefaultMaxRepos
+func MaxRepos() int {
+	if n := viper.GetInt("github.max_repos"); n > 0 {
+		return n
+	}
+	return DefaultMaxRepos
+}
+
+// RateLimit is the state of the core REST API rate limit
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimit reads the core rate limit. The rate_limit endpoint does not
+// count against it.
+func (c *Client) RateLimit(ctx context.Context) (RateLimit, error) {
+	output, err := runGitHubCLIWithRetry(ctx, "api", "rate_limit")
+	if err != nil {
+		return RateLimit{}, err
+	}
+	return parseRateLimit(output)
+}
+
+func parseRateLimit(output string) (RateLimit, error) {
+	var raw struct {
+		Resources struct {
+			Core struct {
+				Limit     int   `json:"limit"`
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal([]byte(output), &raw); err != nil {
+		return RateLimit{}, fmt.Errorf("failed to parse rate limit: %w", err)
+	}
+	core := raw.Resources.Core
+	return RateLimit{Limit: core.Limit, Remaining: core.Remaining, Reset: time.Unix(core.Reset, 0)}, nil
+}
+
+// ForRepo returns a client for owner/repo that shares c's credentials
+func (c *Client) ForRepo(owner, repo string) *Client {
+	return &Client{client: c.client, owner: owner, repo: repo, resolvedOnce: true}
+}
+
+// ExpandRepos resolves owner/* entries into the owner's repositories,
+// most recently pushed first, skipping archived ones and stopping at
+// MaxRepos in total. The result is sorted and without duplicates.
+func (c *Client) ExpandRepos(ctx context.Context, entries []string) ([]string, error) {
+	limit := MaxRepos()
+	seen := map[string]bool{}
+	var repos []string
+	add := func(full string) {
+		if !seen[strings.ToLower(full)] && len(repos) < limit {
+			seen[strings.ToLower(full)] = true
+			repos = append(repos, full)
+		}
+	}
+	for _, entry := range NormalizeRepos(entries) {
+		owner, name, _ := strings.Cut(entry, "/")
+		if name != "*" {
+			add(entry)
+			continue
+		}
+		names, err := c.listOwnerRepositories(ctx, owner, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, full := range names {
+			add(full)
+		}
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+// listOwnerRepositories lists the unarchived repositories of an
+// organization, or of a user when owner is not an organization
+func (c *Client) listOwnerRepositories(ctx context.Context, owner string, limit int) ([]string, error) {
+	names, err := listRepositoriesAt(ctx, "orgs/"+owner+"/repos", limit)
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "404") {
+		names, err = listRepositoriesAt(ctx, "users/"+owner+"/repos", limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repositories of %s: %w", owner, err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s has no active repositories", owner)
+	}
+	return names, nil
+}
+
+func listRepositoriesAt(ctx context.Context, endpoint string, limit int) ([]string, error) {
+	var names []string
+	for page := 1; len(names) < limit; page++ {
+		output, err := runGitHubCLIWithRetry(ctx, "api", fmt.Sprintf("%s?per_page=100&page=%d&sort=pushed&type=all", endpoint, page))
+		if err != nil {
+			return nil, err
+		}
+		var raw []struct {
+			FullName string `json:"full_name"`
+			Archived bool   `json:"archived"`
+			Disabled bool   `json:"disabled"`
+		}
+		if err := json.Unmarshal([]byte(output), &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", endpoint, err)
+		}
+		for _, r := range raw {
+			if !r.Archived && !r.Disabled && len(names) < limit {
+				names = append(names, r.FullName)
+			}
+		}
+		if len(raw) < 100 {
+			break
+		}
+	}
+	return names, nil
+}
+
+// GetMultiRepoContext gathers the context for question from every
+// repository entries names, expanding owner/* entries. Repositories are
+// queried RepoConcurrency at a time; before each batch the rate limit is
+// checked, and when it cannot cover the batch and does not reset soon the
+// remaining repositories are skipped and listed instead.
+func (c *Client) GetMultiRepoContext(ctx context.Context, entries []string, question string) (string, error) {
+	repos, err := c.ExpandRepos(ctx, entries)
+	if err != nil {
+		return "", err
+	}
+	if len(repos) == 0 {
+		return "", fmt.Errorf("github.repos names no repository as owner/name or owner/*")
+	}
+	fanOut := repoFanOut{
+		concurrency: RepoConcurrency(),
+		rateLimit:   c.RateLimit,
+		fetch: func(ctx context.Context, full string) (string, error) {
+			owner, name, _ := strings.Cut(full, "/")
+			return c.ForRepo(owner, name).relevantContext(ctx, question, false)
+		},
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+	return fanOut.run(ctx, repos)
+}
+
+// repoFanOut queries repositories in rate-limit-aware batches
+type repoFanOut struct {
+	concurrency int
+	rateLimit   func(ctx context.Context) (RateLimit, error)
+	fetch       func(ctx context.Context, repo string) (string, error)
+	now         func() time.Time
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+func (f repoFanOut) run(ctx context.Context, repos []string) (string, error) {
+	size := f.concurrency
+	if size <= 0 {
+		size = DefaultRepoConcurrency
+	}
+	sections := make([]string, len(repos))
+	failed := make([]bool, len(repos))
+	done := 0
+	skipReason := ""
+	for start := 0; start < len(repos) && skipReason == ""; start += size {
+		end := min(start+size, len(repos))
+		if reason := f.waitForRateLimit(ctx, end-start); reason != "" {
+			skipReason = reason
+			break
+		}
+
+		var mu sync.Mutex
+		limited := false
+		var g errgroup.Group
+		for i := start; i < end; i++ {
+			g.Go(func() error {
+				content, err := f.fetch(ctx, repos[i])
+				if err != nil {
+					failed[i] = true
+					content = fmt.Sprintf("Unavailable: %v\n", err)
+					if isRateLimitError(err) {
+						mu.Lock()
+						limited = true
+						mu.Unlock()
+					}
+				}
+				sections[i] = fmt.Sprintf("=== Repository: %s ===\n%s", repos[i], content)
+				return nil
+			})
+		}
+		_ = g.Wait()
+		done = end
+		if limited {
+			skipReason = "GitHub rate limited the last batch"
+		}
+	}
+
+	reached := 0
+	for i := 0; i < done; i++ {
+		if !failed[i] {
+			reached++
+		}
+	}
+	if reached == 0 {
+		if done == 0 {
+			return "", fmt.Errorf("no repository was queried: %s", skipReason)
+		}
+		return "", fmt.Errorf("no repository could be queried: %s", strings.TrimSpace(sections[0]))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "GitHub: %d of %d repositories queried\n", reached, len(repos))
+	if done < len(repos) {
+		fmt.Fprintf(&sb, "Skipped %d repositories (%s): %s\n", len(repos)-done, skipReason, strings.Join(repos[done:], ", "))
+	}
+	sb.WriteString("\n")
+	for _, section := range sections[:done] {
+		sb.WriteString(section)
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// waitForRateLimit waits until the rate limit can cover a batch of n
+// repositories, and returns why the batch cannot run when it would wait
+// longer than maxRateLimitWait. An unreadable rate limit does not block.
+func (f repoFanOut) waitForRateLimit(ctx context.Context, n int) string {
+	if f.rateLimit == nil {
+		return ""
+	}
+	rl, err := f.rateLimit(ctx)
+	if err != nil || rl.Limit == 0 || rl.Remaining >= n*callsPerRepo {
+		return ""
+	}
+	wait := rl.Reset.Sub(f.now())
+	if wait > maxRateLimitWait {
+		return fmt.Sprintf("rate limit has %d of %d calls left until %s", rl.Remaining, rl.Limit, rl.Reset.Local().Format("15:04"))
+	}
+	if wait > 0 {
+		if err := f.sleep(ctx, wait); err != nil {
+			return err.Error()
+		}
+	}
+	return ""
+}
+
+func isRateLimitError(err error) bool {
+	lower := strings.ToLower(err.Error())
+	return strings.Contains(lower, "rate limit") || strings.Contains(lower, "http 429")
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
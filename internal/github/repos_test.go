@@ -0,0 +1,104 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNormalizeRepos(t *testing.T) {
+	got := NormalizeRepos([]string{" acme/shop ", "acme/api.git,acme/*", "ACME/shop", "bad", "a/b/c", "/x"})
+	want := []string{"acme/shop", "acme/api", "acme/*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeRepos = %v, want %v", got, want)
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	rl, err := parseRateLimit(`{"resources": {"core": {"limit": 5000, "remaining": 42, "reset": 1760529600}}}`)
+	if err != nil || rl.Limit != 5000 || rl.Remaining != 42 || !rl.Reset.Equal(time.Unix(1760529600, 0)) {
+		t.Errorf("parseRateLimit = %+v, %v", rl, err)
+	}
+}
+
+func TestRepoFanOut(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	repos := []string{"acme/a", "acme/b", "acme/c", "acme/d", "acme/e"}
+	var inFlight, peak int32
+	fetch := func(ctx context.Context, repo string) (string, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		if repo == "acme/c" {
+			return "", errors.New("HTTP 404: Not Found")
+		}
+		return "runs of " + repo + "\n", nil
+	}
+
+	out, err := repoFanOut{concurrency: 2, fetch: fetch, now: func() time.Time { return now }}.run(context.Background(), repos)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if peak > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", peak)
+	}
+	if !strings.HasPrefix(out, "GitHub: 4 of 5 repositories queried\n") ||
+		!strings.Contains(out, "=== Repository: acme/c ===\nUnavailable: HTTP 404") ||
+		strings.Index(out, "acme/a ===") > strings.Index(out, "acme/e ===") {
+		t.Errorf("output:\n%s", out)
+	}
+}
+
+func TestRepoFanOutRateLimit(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	repos := []string{"acme/a", "acme/b", "acme/c"}
+	fetch := func(ctx context.Context, repo string) (string, error) { return "ok\n", nil }
+
+	// Enough for the first batch only, resetting in an hour
+	remaining := 2 * callsPerRepo
+	rateLimit := func(ctx context.Context) (RateLimit, error) {
+		rl := RateLimit{Limit: 5000, Remaining: remaining, Reset: now.Add(time.Hour)}
+		remaining -= 2 * callsPerRepo
+		return rl, nil
+	}
+	out, err := repoFanOut{concurrency: 2, rateLimit: rateLimit, fetch: fetch, now: func() time.Time { return now }}.run(context.Background(), repos)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if !strings.Contains(out, "GitHub: 2 of 3 repositories queried") || !strings.Contains(out, "Skipped 1 repositories (rate limit has 0 of 5000 calls left") || !strings.Contains(out, ": acme/c\n") {
+		t.Errorf("output:\n%s", out)
+	}
+
+	// A reset within maxRateLimitWait is waited out
+	var slept time.Duration
+	rateLimit = func(ctx context.Context) (RateLimit, error) {
+		return RateLimit{Limit: 60, Remaining: 0, Reset: now.Add(10 * time.Second)}, nil
+	}
+	sleep := func(ctx context.Context, d time.Duration) error { slept += d; return nil }
+	out, err = repoFanOut{concurrency: 3, rateLimit: rateLimit, fetch: fetch, now: func() time.Time { return now }, sleep: sleep}.run(context.Background(), repos)
+	if err != nil || slept != 10*time.Second || !strings.Contains(out, "3 of 3 repositories queried") {
+		t.Errorf("run = %v, slept %s:\n%s", err, slept, out)
+	}
+
+	// A rate-limited batch stops the ones after it
+	limited := func(ctx context.Context, repo string) (string, error) {
+		if repo == "acme/b" {
+			return "", errors.New("HTTP 403: API rate limit exceeded")
+		}
+		return "ok\n", nil
+	}
+	out, err = repoFanOut{concurrency: 2, fetch: limited, now: func() time.Time { return now }}.run(context.Background(), repos)
+	if err != nil || !strings.Contains(out, "1 of 3 repositories queried") || !strings.Contains(out, "Skipped 1 repositories (GitHub rate limited the last batch): acme/c") {
+		t.Errorf("run = %v:\n%s", err, out)
+	}
+}
@@ -0,0 +1,270 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Risk levels, in increasing order
+const (
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+var riskRank = map[string]int{RiskLow: 1, RiskMedium: 2, RiskHigh: 3}
+
+// Finding is one risky change the review flags
+type Finding struct {
+	File     string `json:"file"`
+	Severity string `json:"severity"`
+	Issue    string `json:"issue"`
+}
+
+// Review is the structured review of a pull request
+type Review struct {
+	PR    PullRequest
+	Files []File
+	// Summary says what the pull request changes, in a few sentences
+	Summary string
+	// Risk is the overall risk level: low, medium or high
+	Risk         string
+	RiskAreas    []Finding
+	MissingTests []string
+	InfraChanges []string
+	// NotReviewed lists the files whose diff was past MaxChunks
+	NotReviewed []string
+}
+
+// chunkAnswer is the JSON the AI client returns for one chunk
+type chunkAnswer struct {
+	Summary      string    `json:"summary"`
+	Risk         string    `json:"risk"`
+	RiskAreas    []Finding `json:"risk_areas"`
+	MissingTests []string  `json:"missing_tests"`
+	InfraChanges []string  `json:"infra_changes"`
+}
+
+// maxBodyInPrompt caps the PR description quoted in each prompt
+const maxBodyInPrompt = 2000
+
+// ReviewDiff reviews the files of pr. The diff is sent in chunks of at most
+// ChunkSize bytes; when it takes more than one, a last prompt merges the
+// per-chunk summaries into one.
+func (a *Agent) ReviewDiff(ctx context.Context, pr PullRequest, files []File) (*Review, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("pull request #%d changes no files", pr.Number)
+	}
+	size := a.ChunkSize
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	chunks := chunkFiles(files, size)
+	r := &Review{PR: pr, Files: files}
+	if a.MaxChunks > 0 && len(chunks) > a.MaxChunks {
+		reviewed := map[string]bool{}
+		for _, c := range chunks[:a.MaxChunks] {
+			for _, p := range c.paths {
+				reviewed[p] = true
+			}
+		}
+		for _, c := range chunks[a.MaxChunks:] {
+			for _, p := range c.paths {
+				if !reviewed[p] {
+					r.NotReviewed = append(r.NotReviewed, p)
+					reviewed[p] = true
+				}
+			}
+		}
+		chunks = chunks[:a.MaxChunks]
+	}
+
+	var summaries []string
+	for i, c := range chunks {
+		var ans chunkAnswer
+		if err := a.askJSON(ctx, chunkPrompt(pr, files, c, i+1, len(chunks)), &ans); err != nil {
+			return nil, fmt.Errorf("failed to review part %d of %d of the diff: %w", i+1, len(chunks), err)
+		}
+		summaries = append(summaries, strings.TrimSpace(ans.Summary))
+		r.Risk = maxRisk(r.Risk, ans.Risk)
+		r.RiskAreas = append(r.RiskAreas, ans.RiskAreas...)
+		r.MissingTests = append(r.MissingTests, ans.MissingTests...)
+		r.InfraChanges = append(r.InfraChanges, ans.InfraChanges...)
+	}
+
+	r.Summary = summaries[0]
+	if len(summaries) > 1 {
+		var ans chunkAnswer
+		if err := a.askJSON(ctx, synthesisPrompt(pr, summaries, r), &ans); err != nil {
+			return nil, fmt.Errorf("failed to summarize the review: %w", err)
+		}
+		r.Summary = strings.TrimSpace(ans.Summary)
+		r.Risk = maxRisk(r.Risk, ans.Risk)
+	}
+
+	r.addChecks()
+	r.RiskAreas = dedupeFindings(r.RiskAreas)
+	r.MissingTests = dedupe(r.MissingTests)
+	r.InfraChanges = dedupe(r.InfraChanges)
+	if r.Risk == "" {
+		r.Risk = RiskLow
+	}
+	return r, nil
+}
+
+// askJSON sends prompt and decodes the answer into v
+func (a *Agent) askJSON(ctx context.Context, prompt string, v interface{}) error {
+	if a.debug {
+		fmt.Printf("[pr-review] prompt of %d bytes\n", len(prompt))
+	}
+	resp, err := a.ask(ctx, prompt)
+	if err != nil {
+		return err
+	}
+	if a.clean != nil {
+		resp = a.clean(resp)
+	}
+	if err := json.Unmarshal([]byte(resp), v); err != nil {
+		return fmt.Errorf("AI answer is not the expected JSON: %w", err)
+	}
+	return nil
+}
+
+// addChecks adds what the file list alone shows, so that an answer that
+// misses it does not hide it: source changed without tests, and infra,
+// CI, dependency and migration files the AI client did not mention
+func (r *Review) addChecks() {
+	var source, tests int
+	for _, f := range r.Files {
+		switch f.Kind {
+		case Source:
+			if f.Status != "removed" {
+				source++
+			}
+		case Test:
+			tests++
+		}
+	}
+	if source > 0 && tests == 0 {
+		r.MissingTests = append([]string{fmt.Sprintf("No test files changed alongside %d source %s", source, plural(source, "file", "files"))}, r.MissingTests...)
+	}
+
+	mentioned := strings.ToLower(strings.Join(r.InfraChanges, "\n"))
+	for _, f := range r.Files {
+		switch f.Kind {
+		case Infra, CI, Dependencies, Migration:
+		default:
+			continue
+		}
+		if strings.Contains(mentioned, strings.ToLower(f.Path)) {
+			continue
+		}
+		r.InfraChanges = append(r.InfraChanges, fmt.Sprintf("%s (%s, %s)", f.Path, f.Kind, f.Status))
+	}
+	if len(r.InfraChanges) > 0 && riskRank[r.Risk] < riskRank[RiskMedium] {
+		r.Risk = RiskMedium
+	}
+}
+
+// chunkPrompt asks for the review of one chunk of the diff
+func chunkPrompt(pr PullRequest, files []File, c chunk, part, parts int) string {
+	var b strings.Builder
+	b.WriteString("You are reviewing a GitHub pull request. Flag risky changes: behaviour changes, security issues, ")
+	b.WriteString("data loss, breaking API or schema changes, concurrency and error handling mistakes. ")
+	b.WriteString("List source changes that lack tests, and describe any infrastructure, CI, dependency or database migration change.\n\n")
+	fmt.Fprintf(&b, "Pull request: %s#%d %q by %s, %s into %s (+%d -%d in %d files)\n",
+		pr.Repo, pr.Number, pr.Title, pr.Author, pr.Head, pr.Base, pr.Additions, pr.Deletions, len(files))
+	if body := strings.TrimSpace(pr.Body); body != "" {
+		if len(body) > maxBodyInPrompt {
+			body = body[:maxBodyInPrompt] + "..."
+		}
+		fmt.Fprintf(&b, "Description:\n%s\n", body)
+	}
+	b.WriteString("\nAll changed files:\n")
+	for _, f := range files {
+		fmt.Fprintf(&b, "- %s (%s, %s)\n", f.Path, f.Status, f.Kind)
+	}
+	if parts > 1 {
+		fmt.Fprintf(&b, "\nThis is part %d of %d of the diff; review only the files below.\n", part, parts)
+	}
+	fmt.Fprintf(&b, "\nDiff:\n%s\n", c.text)
+	b.WriteString(`Respond with JSON only, in this shape:
+{"summary": "2-3 sentences on what these changes do",
+ "risk": "low|medium|high",
+ "risk_areas": [{"file": "path", "severity": "low|medium|high", "issue": "what could go wrong"}],
+ "missing_tests": ["change that should be tested"],
+ "infra_changes": ["path: what changes in infrastructure, CI, dependencies or the database"]}
+Use empty arrays when there is nothing to report. Do not invent issues the diff does not show.`)
+	return b.String()
+}
+
+// synthesisPrompt asks for one summary of a diff reviewed in parts
+func synthesisPrompt(pr PullRequest, summaries []string, r *Review) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The diff of pull request %s#%d %q was reviewed in %d parts.\n\n", pr.Repo, pr.Number, pr.Title, len(summaries))
+	for i, s := range summaries {
+		fmt.Fprintf(&b, "Part %d: %s\n", i+1, s)
+	}
+	if len(r.RiskAreas) > 0 {
+		b.WriteString("\nFlagged risks:\n")
+		for _, f := range r.RiskAreas {
+			fmt.Fprintf(&b, "- [%s] %s: %s\n", f.Severity, f.File, f.Issue)
+		}
+	}
+	b.WriteString(`
+Write one summary of the whole pull request and its overall risk. Respond with JSON only:
+{"summary": "2-4 sentences", "risk": "low|medium|high"}`)
+	return b.String()
+}
+
+// maxRisk returns the higher of two risk levels, ignoring unknown values
+func maxRisk(a, b string) string {
+	b = strings.ToLower(strings.TrimSpace(b))
+	if riskRank[b] > riskRank[a] {
+		return b
+	}
+	return a
+}
+
+func dedupeFindings(findings []Finding) []Finding {
+	seen := map[string]bool{}
+	var out []Finding
+	for _, f := range findings {
+		f.Severity = strings.ToLower(strings.TrimSpace(f.Severity))
+		if _, ok := riskRank[f.Severity]; !ok {
+			f.Severity = RiskMedium
+		}
+		key := f.File + "\x00" + strings.ToLower(strings.TrimSpace(f.Issue))
+		if strings.TrimSpace(f.Issue) == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, f)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return riskRank[out[i].Severity] > riskRank[out[j].Severity] })
+	return out
+}
+
+func dedupe(values []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[strings.ToLower(v)] {
+			continue
+		}
+		seen[strings.ToLower(v)] = true
+		out = append(out, v)
+	}
+	return out
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,136 @@
+package review
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Kind is what a changed file is, for the review's infra and test checks
+type Kind string
+
+const (
+	Source       Kind = "source"
+	Test         Kind = "test"
+	Infra        Kind = "infra"
+	CI           Kind = "ci"
+	Dependencies Kind = "dependencies"
+	Migration    Kind = "migration"
+	Docs         Kind = "docs"
+	Other        Kind = "other"
+)
+
+var (
+	testFileRe  = regexp.MustCompile(`(?i)(?:_test\.go|\.(?:test|spec)\.[jt]sx?|_spec\.rb|(?:^|/)test_[^/]+\.py|_test\.py|Test\.java|Tests?\.cs|\.snap)$|(?:^|/)(?:tests?|__tests__|spec|testdata|e2e)/`)
+	infraRe     = regexp.MustCompile(`(?i)\.(?:tf|tfvars|hcl|bicep)$|(?:^|/)(?:Dockerfile[^/]*|docker-compose[^/]*\.ya?ml|compose\.ya?ml|Chart\.yaml|values[^/]*\.ya?ml|kustomization\.ya?ml|serverless\.ya?ml|template\.ya?ml|cdk\.json|Pulumi[^/]*\.ya?ml|fly\.toml|vercel\.json|wrangler\.toml|app\.yaml|Procfile)$|(?:^|/)(?:k8s|kubernetes|manifests|helm|charts|deploy|deployment|infra|infrastructure|terraform|cloudformation|ansible)/`)
+	ciRe        = regexp.MustCompile(`(?i)^\.github/(?:workflows|actions)/|(?:^|/)(?:\.gitlab-ci\.yml|Jenkinsfile|\.circleci/|buildspec\.ya?ml|cloudbuild\.ya?ml|azure-pipelines\.ya?ml|\.buildkite/)`)
+	depsRe      = regexp.MustCompile(`(?i)(?:^|/)(?:go\.(?:mod|sum)|package(?:-lock)?\.json|yarn\.lock|pnpm-lock\.yaml|requirements[^/]*\.txt|Pipfile(?:\.lock)?|poetry\.lock|pyproject\.toml|Gemfile(?:\.lock)?|Cargo\.(?:toml|lock)|pom\.xml|build\.gradle(?:\.kts)?|composer\.(?:json|lock))$`)
+	migrationRe = regexp.MustCompile(`(?i)(?:^|/)(?:migrations?|migrate|db/migrate|alembic/versions)/|\.sql$`)
+	docsRe      = regexp.MustCompile(`(?i)\.(?:md|mdx|rst|txt|adoc)$|(?:^|/)docs?/|(?:^|/)LICENSE[^/]*$`)
+	sourceExt   = map[string]bool{
+		".go": true, ".py": true, ".js": true, ".jsx": true, ".ts": true, ".tsx": true, ".rb": true, ".java": true,
+		".kt": true, ".cs": true, ".rs": true, ".php": true, ".swift": true, ".scala": true, ".c": true, ".cc": true,
+		".cpp": true, ".h": true, ".hpp": true, ".ex": true, ".exs": true, ".vue": true, ".svelte": true,
+	}
+)
+
+// Classify says what kind of file p is. Tests are recognized before
+// source so that foo_test.go is a test, and CI before infra so that a
+// workflow's deploy job is a CI change.
+func Classify(p string) Kind {
+	switch {
+	case testFileRe.MatchString(p):
+		return Test
+	case ciRe.MatchString(p):
+		return CI
+	case depsRe.MatchString(p):
+		return Dependencies
+	case migrationRe.MatchString(p):
+		return Migration
+	case infraRe.MatchString(p):
+		return Infra
+	case docsRe.MatchString(p):
+		return Docs
+	case sourceExt[strings.ToLower(path.Ext(p))]:
+		return Source
+	}
+	return Other
+}
+
+// chunk is a run of file diffs sent to the AI client in one prompt
+type chunk struct {
+	text  string
+	paths []string
+}
+
+// chunkFiles packs the file diffs into chunks of at most size bytes, in
+// file order. A file whose diff is larger than size is split at hunk
+// boundaries, or at line boundaries within a hunk that is itself too
+// large. Files without a patch are listed by name only.
+func chunkFiles(files []File, size int) []chunk {
+	var chunks []chunk
+	var cur strings.Builder
+	var curPaths []string
+	flush := func() {
+		if cur.Len() > 0 {
+			chunks = append(chunks, chunk{text: cur.String(), paths: curPaths})
+		}
+		cur.Reset()
+		curPaths = nil
+	}
+	add := func(p, text string) {
+		if cur.Len()+len(text) > size {
+			flush()
+		}
+		cur.WriteString(text)
+		if len(curPaths) == 0 || curPaths[len(curPaths)-1] != p {
+			curPaths = append(curPaths, p)
+		}
+	}
+
+	for _, f := range files {
+		header := fmt.Sprintf("--- %s (%s, %s, +%d -%d)\n", f.Path, f.Status, f.Kind, f.Additions, f.Deletions)
+		if f.Patch == "" {
+			add(f.Path, header+"(no diff: binary or too large to show)\n\n")
+			continue
+		}
+		for i, part := range splitPatch(f.Patch, size-len(header)-16) {
+			h := header
+			if i > 0 {
+				h = fmt.Sprintf("--- %s (continued)\n", f.Path)
+			}
+			add(f.Path, h+part+"\n\n")
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitPatch splits a unified diff into pieces of at most size bytes,
+// preferring to cut before a hunk header
+func splitPatch(patch string, size int) []string {
+	if size < 256 {
+		size = 256
+	}
+	if len(patch) <= size {
+		return []string{patch}
+	}
+	var parts []string
+	var cur strings.Builder
+	for _, line := range strings.SplitAfter(patch, "\n") {
+		if len(line) > size {
+			line = line[:size-4] + "...\n"
+		}
+		startsHunk := strings.HasPrefix(line, "@@")
+		if cur.Len() > 0 && (cur.Len()+len(line) > size || startsHunk && cur.Len() > size/2) {
+			parts = append(parts, strings.TrimRight(cur.String(), "\n"))
+			cur.Reset()
+		}
+		cur.WriteString(line)
+	}
+	if cur.Len() > 0 {
+		parts = append(parts, strings.TrimRight(cur.String(), "\n"))
+	}
+	return parts
+}
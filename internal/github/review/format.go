@@ -0,0 +1,72 @@
+package review
+
+import (
+	"fmt"
+	"strings"
+)
+
+// commentMarker ends every posted review so it can be recognized later
+const commentMarker = "<!-- clanker-pr-review -->"
+
+// Format renders the review as markdown, the same for the terminal and
+// for the comment posted on the pull request
+func (r *Review) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Review of #%d: %s\n\n", r.PR.Number, r.PR.Title)
+	state := r.PR.State
+	if r.PR.Draft {
+		state = "draft"
+	}
+	fmt.Fprintf(&b, "**Risk: %s** · %s into `%s` · %d %s, +%d -%d",
+		strings.ToUpper(r.Risk), state, r.PR.Base, len(r.Files), plural(len(r.Files), "file", "files"), r.PR.Additions, r.PR.Deletions)
+	if r.PR.HeadSHA != "" {
+		fmt.Fprintf(&b, " · at %s", shortSHA(r.PR.HeadSHA))
+	}
+	b.WriteString("\n\n")
+	if r.Summary != "" {
+		fmt.Fprintf(&b, "%s\n\n", r.Summary)
+	}
+
+	b.WriteString("### Risk areas\n\n")
+	if len(r.RiskAreas) == 0 {
+		b.WriteString("None found.\n")
+	}
+	for _, f := range r.RiskAreas {
+		if f.File != "" {
+			fmt.Fprintf(&b, "- **%s** `%s`: %s\n", f.Severity, f.File, f.Issue)
+		} else {
+			fmt.Fprintf(&b, "- **%s** %s\n", f.Severity, f.Issue)
+		}
+	}
+
+	b.WriteString("\n### Missing tests\n\n")
+	writeList(&b, r.MissingTests)
+	b.WriteString("\n### Infrastructure changes\n\n")
+	writeList(&b, r.InfraChanges)
+
+	if len(r.NotReviewed) > 0 {
+		fmt.Fprintf(&b, "\n### Not reviewed\n\nThe diff was too large to review in full; %d %s skipped:\n\n",
+			len(r.NotReviewed), plural(len(r.NotReviewed), "file was", "files were"))
+		writeList(&b, r.NotReviewed)
+	}
+
+	fmt.Fprintf(&b, "\n_Generated by clanker; an AI review can miss issues and is no substitute for a human one._\n%s\n", commentMarker)
+	return b.String()
+}
+
+func writeList(b *strings.Builder, items []string) {
+	if len(items) == 0 {
+		b.WriteString("None.\n")
+		return
+	}
+	for _, item := range items {
+		fmt.Fprintf(b, "- %s\n", item)
+	}
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
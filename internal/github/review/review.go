@@ -0,0 +1,243 @@
+// Package review summarizes a pull request and flags its risky changes. It
+// fetches the diff, classifies the changed files, sends the diff to the AI
+// client in chunks that fit its context, and merges the answers into one
+// structured review that can be printed or posted as a PR comment.
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Runner runs a gh CLI command and returns its output
+type Runner func(ctx context.Context, args []string) (string, error)
+
+// AskFunc sends a prompt to the AI client and returns its answer
+type AskFunc func(ctx context.Context, prompt string) (string, error)
+
+// CleanFunc strips markdown fences and prose around a JSON answer
+type CleanFunc func(response string) string
+
+// Agent fetches pull requests and reviews them
+type Agent struct {
+	run   Runner
+	ask   AskFunc
+	clean CleanFunc
+	debug bool
+	// ChunkSize is the most diff text, in bytes, sent in one prompt
+	ChunkSize int
+	// MaxChunks caps the prompts one review sends; files past it are
+	// listed as not reviewed
+	MaxChunks int
+}
+
+// Chunking defaults, sized to leave room for the prompt and answer in the
+// smallest context window the AI providers offer
+const (
+	DefaultChunkSize = 24000
+	DefaultMaxChunks = 8
+)
+
+// NewAgent creates a review agent that reads GitHub through run and the AI
+// client through ask and clean
+func NewAgent(run Runner, ask AskFunc, clean CleanFunc, debug bool) *Agent {
+	return &Agent{run: run, ask: ask, clean: clean, debug: debug, ChunkSize: DefaultChunkSize, MaxChunks: DefaultMaxChunks}
+}
+
+// PullRequest is the pull request a review covers
+type PullRequest struct {
+	Repo         string
+	Number       int
+	Title        string
+	Body         string
+	Author       string
+	State        string
+	Draft        bool
+	Base         string
+	Head         string
+	HeadSHA      string
+	URL          string
+	Additions    int
+	Deletions    int
+	ChangedFiles int
+}
+
+// File is one changed file with its patch. Patch is empty for binary files
+// and for diffs GitHub considers too large to show.
+type File struct {
+	Path      string
+	Status    string
+	Additions int
+	Deletions int
+	Patch     string
+	Kind      Kind
+}
+
+// Request is a question translated into a review
+type Request struct {
+	// Repo is owner/name when the question names a repository
+	Repo   string
+	Number int
+	// Post adds the review to the pull request as a comment
+	Post bool
+}
+
+var (
+	prURLRe    = regexp.MustCompile(`github\.com/([\w.-]+/[\w.-]+)/pull/(\d+)`)
+	prNumberRe = regexp.MustCompile(`(?i)\b(?:pr|pull request|pull|merge request)\s*(?:number\s+|no\.?\s*)?#?(\d+)\b|(?:^|\s)#(\d+)\b`)
+	prRepoRe   = regexp.MustCompile(`(?i)\b(?:repo(?:sitory)?|in|of|on|for)\s+["'` + "`" + `]?([\w.-]+/[\w.-]+)\b`)
+	reviewRe   = regexp.MustCompile(`(?i)\b(?:summari[sz]e|summary|review|risky|risks?|flag|analy[sz]e|audit|what changed|what does .* change)\b`)
+	prWordRe   = regexp.MustCompile(`(?i)\b(?:pr|pull request|pull/\d+)\b`)
+	postRe     = regexp.MustCompile(`(?i)\b(?:post|comment|leave|add)\b.*\b(?:comment|review|it|pr|pull request)\b|--post\b`)
+)
+
+// IsReviewQuestion reports whether question asks to summarize or review a
+// numbered pull request
+func IsReviewQuestion(question string) bool {
+	if !reviewRe.MatchString(question) || !prWordRe.MatchString(question) {
+		return false
+	}
+	_, ok := ParseRequest(question)
+	return ok
+}
+
+// ParseRequest reads the pull request a question names, and whether the
+// review should be posted
+func ParseRequest(question string) (Request, bool) {
+	var req Request
+	rest := question
+	if m := prURLRe.FindStringSubmatch(rest); m != nil {
+		req.Repo = m[1]
+		req.Number, _ = strconv.Atoi(m[2])
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+	if m := prRepoRe.FindStringSubmatch(rest); m != nil && req.Repo == "" {
+		req.Repo = strings.TrimSuffix(m[1], ".git")
+	}
+	if req.Number == 0 {
+		if m := prNumberRe.FindStringSubmatch(rest); m != nil {
+			req.Number, _ = strconv.Atoi(m[1] + m[2])
+		}
+	}
+	req.Post = postRe.MatchString(rest)
+	return req, req.Number > 0
+}
+
+// runJSON runs gh and decodes its JSON output into v
+func (a *Agent) runJSON(ctx context.Context, v interface{}, args ...string) error {
+	if a.debug {
+		fmt.Printf("[pr-review] gh %s\n", strings.Join(args, " "))
+	}
+	out, err := a.run(ctx, args)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(out), v); err != nil {
+		return fmt.Errorf("failed to parse gh %s output: %w", args[0], err)
+	}
+	return nil
+}
+
+// PullRequest reads pull request number of repo
+func (a *Agent) PullRequest(ctx context.Context, repo string, number int) (PullRequest, error) {
+	var raw struct {
+		Number       int    `json:"number"`
+		Title        string `json:"title"`
+		Body         string `json:"body"`
+		State        string `json:"state"`
+		Draft        bool   `json:"draft"`
+		Merged       bool   `json:"merged"`
+		HTMLURL      string `json:"html_url"`
+		Additions    int    `json:"additions"`
+		Deletions    int    `json:"deletions"`
+		ChangedFiles int    `json:"changed_files"`
+		User         struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Base struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+		Head struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := a.runJSON(ctx, &raw, "api", fmt.Sprintf("repos/%s/pulls/%d", repo, number)); err != nil {
+		lower := strings.ToLower(err.Error())
+		if strings.Contains(lower, "404") || strings.Contains(lower, "not found") {
+			return PullRequest{}, fmt.Errorf("%s has no pull request #%d", repo, number)
+		}
+		return PullRequest{}, fmt.Errorf("failed to read pull request #%d: %w", number, err)
+	}
+	state := raw.State
+	if raw.Merged {
+		state = "merged"
+	}
+	return PullRequest{
+		Repo: repo, Number: raw.Number, Title: raw.Title, Body: raw.Body, Author: raw.User.Login,
+		State: state, Draft: raw.Draft, Base: raw.Base.Ref, Head: raw.Head.Ref, HeadSHA: raw.Head.SHA,
+		URL: raw.HTMLURL, Additions: raw.Additions, Deletions: raw.Deletions, ChangedFiles: raw.ChangedFiles,
+	}, nil
+}
+
+// maxFilePages caps the files endpoint, which GitHub stops serving after
+// 3000 files
+const maxFilePages = 30
+
+// Files reads every changed file of pull request number with its patch
+func (a *Agent) Files(ctx context.Context, repo string, number int) ([]File, error) {
+	var files []File
+	for page := 1; page <= maxFilePages; page++ {
+		var raw []struct {
+			Filename  string `json:"filename"`
+			Status    string `json:"status"`
+			Additions int    `json:"additions"`
+			Deletions int    `json:"deletions"`
+			Patch     string `json:"patch"`
+		}
+		query := url.Values{"per_page": {"100"}, "page": {strconv.Itoa(page)}}
+		if err := a.runJSON(ctx, &raw, "api", fmt.Sprintf("repos/%s/pulls/%d/files?%s", repo, number, query.Encode())); err != nil {
+			return nil, fmt.Errorf("failed to read the files of pull request #%d: %w", number, err)
+		}
+		for _, f := range raw {
+			files = append(files, File{
+				Path: f.Filename, Status: f.Status, Additions: f.Additions, Deletions: f.Deletions,
+				Patch: f.Patch, Kind: Classify(f.Filename),
+			})
+		}
+		if len(raw) < 100 {
+			break
+		}
+	}
+	return files, nil
+}
+
+// Review fetches pull request number of repo and reviews it
+func (a *Agent) Review(ctx context.Context, repo string, number int) (*Review, error) {
+	pr, err := a.PullRequest(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	files, err := a.Files(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	return a.ReviewDiff(ctx, pr, files)
+}
+
+// Post adds body to pull request number as a comment and returns the
+// comment's URL
+func (a *Agent) Post(ctx context.Context, repo string, number int, body string) (string, error) {
+	var raw struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := a.runJSON(ctx, &raw, "api", "--method", "POST", fmt.Sprintf("repos/%s/issues/%d/comments", repo, number), "-f", "body="+body); err != nil {
+		return "", fmt.Errorf("failed to comment on pull request #%d: %w", number, err)
+	}
+	return raw.HTMLURL, nil
+}
@@ -0,0 +1,216 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeGH answers gh api calls from canned JSON keyed by endpoint
+type fakeGH struct {
+	responses map[string]string
+	calls     [][]string
+}
+
+func (f *fakeGH) run(ctx context.Context, args []string) (string, error) {
+	f.calls = append(f.calls, args)
+	if len(args) > 3 && args[1] == "--method" {
+		return `{"html_url": "https://github.com/acme/shop/pull/123#issuecomment-1"}`, nil
+	}
+	if out, ok := f.responses[args[1]]; ok {
+		return out, nil
+	}
+	return "", errors.New("gh api " + args[1] + " failed: HTTP 404: Not Found")
+}
+
+// fakeAI answers each prompt in turn
+type fakeAI struct {
+	answers []string
+	prompts []string
+}
+
+func (f *fakeAI) ask(ctx context.Context, prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	if len(f.answers) == 0 {
+		return "", errors.New("no answer")
+	}
+	ans := f.answers[0]
+	f.answers = f.answers[1:]
+	return ans, nil
+}
+
+func newFakeGH() *fakeGH {
+	return &fakeGH{responses: map[string]string{
+		"repos/acme/shop/pulls/123": `{"number": 123, "title": "Add checkout retries", "body": "Retries payment calls.",
+			"state": "open", "user": {"login": "dana"}, "base": {"ref": "main"}, "head": {"ref": "retries", "sha": "4f2a9c1d0e8b"},
+			"additions": 40, "deletions": 3, "changed_files": 3}`,
+		"repos/acme/shop/pulls/123/files?page=1&per_page=100": `[
+			{"filename": "internal/checkout/pay.go", "status": "modified", "additions": 30, "deletions": 3,
+			 "patch": "@@ -10,3 +10,30 @@\n-func pay() {\n+func pay() {\n+\tfor i := 0; i < 3; i++ {\n"},
+			{"filename": "terraform/rds.tf", "status": "modified", "additions": 8, "deletions": 0, "patch": "@@ -1 +1,8 @@\n+deletion_protection = false\n"},
+			{"filename": "logo.png", "status": "added", "additions": 0, "deletions": 0}]`,
+	}}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     Request
+		ok       bool
+	}{
+		{"summarize PR #123 and flag risky changes", Request{Number: 123}, true},
+		{"review pull request 42 in acme/shop and post it as a comment", Request{Repo: "acme/shop", Number: 42, Post: true}, true},
+		{"review https://github.com/acme/shop/pull/7", Request{Repo: "acme/shop", Number: 7}, true},
+		{"review my open PRs", Request{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRequest(tt.question)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseRequest(%q) = %+v, %v; want %+v, %v", tt.question, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestIsReviewQuestion(t *testing.T) {
+	for _, q := range []string{"summarize PR #123 and flag risky changes", "what are the risks in pull request 88?"} {
+		if !IsReviewQuestion(q) {
+			t.Errorf("IsReviewQuestion(%q) = false", q)
+		}
+	}
+	for _, q := range []string{"list open pull requests", "review my IAM policies", "summarize the cost of instance 12345"} {
+		if IsReviewQuestion(q) {
+			t.Errorf("IsReviewQuestion(%q) = true", q)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for p, want := range map[string]Kind{
+		"internal/checkout/pay.go":      Source,
+		"internal/checkout/pay_test.go": Test,
+		"web/src/cart.spec.ts":          Test,
+		".github/workflows/deploy.yml":  CI,
+		"go.mod":                        Dependencies,
+		"db/migrations/0042_orders.sql": Migration,
+		"terraform/rds.tf":              Infra,
+		"docker/Dockerfile":             Infra,
+		"docs/setup.md":                 Docs,
+		"logo.png":                      Other,
+	} {
+		if got := Classify(p); got != want {
+			t.Errorf("Classify(%q) = %s, want %s", p, got, want)
+		}
+	}
+}
+
+func TestChunkFilesSplitsLargePatches(t *testing.T) {
+	var patch strings.Builder
+	for h := 0; h < 6; h++ {
+		patch.WriteString("@@ -1,40 +1,40 @@\n")
+		for i := 0; i < 40; i++ {
+			patch.WriteString("+\tline of changed code\n")
+		}
+	}
+	files := []File{{Path: "a.go", Patch: patch.String(), Kind: Source}, {Path: "b.go", Patch: "@@ -1 +1 @@\n+x\n", Kind: Source}}
+	chunks := chunkFiles(files, 2048)
+	if len(chunks) < 3 {
+		t.Fatalf("got %d chunks, want the large patch split", len(chunks))
+	}
+	for i, c := range chunks {
+		if len(c.text) > 2048 {
+			t.Errorf("chunk %d is %d bytes", i, len(c.text))
+		}
+	}
+	if !strings.Contains(chunks[1].text, "--- a.go (continued)") {
+		t.Errorf("second chunk should continue a.go:\n%s", chunks[1].text)
+	}
+	if last := chunks[len(chunks)-1]; last.paths[len(last.paths)-1] != "b.go" {
+		t.Errorf("last chunk paths = %v", last.paths)
+	}
+}
+
+func TestReview(t *testing.T) {
+	gh := newFakeGH()
+	ai := &fakeAI{answers: []string{"```json\n" + `{"summary": "Adds retries to payment calls.", "risk": "medium",
+		"risk_areas": [{"file": "internal/checkout/pay.go", "severity": "high", "issue": "Retries can double charge a card"},
+		               {"file": "internal/checkout/pay.go", "severity": "high", "issue": "Retries can double charge a card"}],
+		"missing_tests": ["Retry loop in pay()"],
+		"infra_changes": ["terraform/rds.tf: disables RDS deletion protection"]}` + "\n```"}}
+	clean := func(s string) string {
+		return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "```json"), "```")
+	}
+	agent := NewAgent(gh.run, ai.ask, clean, false)
+
+	r, err := agent.Review(context.Background(), "acme/shop", 123)
+	if err != nil {
+		t.Fatalf("Review: %v", err)
+	}
+	if len(ai.prompts) != 1 || !strings.Contains(ai.prompts[0], "+\tfor i := 0; i < 3; i++ {") || !strings.Contains(ai.prompts[0], "logo.png (added, other)") {
+		t.Errorf("prompts = %q", ai.prompts)
+	}
+	if r.Risk != RiskHigh && r.Risk != RiskMedium {
+		t.Errorf("risk = %q", r.Risk)
+	}
+	if len(r.RiskAreas) != 1 {
+		t.Errorf("risk areas = %+v, want duplicates merged", r.RiskAreas)
+	}
+	if len(r.MissingTests) != 2 || r.MissingTests[0] != "No test files changed alongside 1 source file" {
+		t.Errorf("missing tests = %v", r.MissingTests)
+	}
+	if len(r.InfraChanges) != 1 {
+		t.Errorf("infra changes = %v, want the file the answer already mentions listed once", r.InfraChanges)
+	}
+
+	out := r.Format()
+	for _, want := range []string{"## Review of #123: Add checkout retries", "**Risk: MEDIUM**", "- **high** `internal/checkout/pay.go`: Retries can double charge a card", commentMarker} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Format() missing %q:\n%s", want, out)
+		}
+	}
+
+	link, err := agent.Post(context.Background(), "acme/shop", 123, out)
+	if err != nil || link == "" {
+		t.Fatalf("Post = %q, %v", link, err)
+	}
+	last := gh.calls[len(gh.calls)-1]
+	if strings.Join(last[:4], " ") != "api --method POST repos/acme/shop/issues/123/comments" || last[5] != "body="+out {
+		t.Errorf("post args = %v", last[:5])
+	}
+}
+
+func TestReviewDiffInParts(t *testing.T) {
+	files := []File{
+		{Path: "a.go", Status: "modified", Kind: Source, Patch: "@@ -1 +1 @@\n+" + strings.Repeat("a", 900) + "\n"},
+		{Path: "a_test.go", Status: "modified", Kind: Test, Patch: "@@ -1 +1 @@\n+" + strings.Repeat("t", 900) + "\n"},
+		{Path: "b.go", Status: "modified", Kind: Source, Patch: "@@ -1 +1 @@\n+" + strings.Repeat("b", 900) + "\n"},
+	}
+	ai := &fakeAI{answers: []string{
+		`{"summary": "Part one.", "risk": "low", "risk_areas": [], "missing_tests": [], "infra_changes": []}`,
+		`{"summary": "Part two.", "risk": "high", "risk_areas": [{"file": "a_test.go", "severity": "bogus", "issue": "Skips the only test"}]}`,
+		`{"summary": "Overall summary.", "risk": "medium"}`,
+	}}
+	agent := NewAgent(nil, ai.ask, nil, false)
+	agent.ChunkSize = 1024
+	agent.MaxChunks = 2
+
+	r, err := agent.ReviewDiff(context.Background(), PullRequest{Repo: "acme/shop", Number: 9}, files)
+	if err != nil {
+		t.Fatalf("ReviewDiff: %v", err)
+	}
+	if len(ai.prompts) != 3 || !strings.Contains(ai.prompts[1], "part 2 of 2") || !strings.Contains(ai.prompts[2], "Part 2: Part two.") {
+		t.Errorf("prompts = %q", ai.prompts)
+	}
+	if r.Summary != "Overall summary." || r.Risk != RiskHigh {
+		t.Errorf("summary, risk = %q, %q", r.Summary, r.Risk)
+	}
+	if len(r.NotReviewed) != 1 || r.NotReviewed[0] != "b.go" {
+		t.Errorf("not reviewed = %v", r.NotReviewed)
+	}
+	if len(r.RiskAreas) != 1 || r.RiskAreas[0].Severity != RiskMedium {
+		t.Errorf("risk areas = %+v, want unknown severity read as medium", r.RiskAreas)
+	}
+	if len(r.MissingTests) != 0 {
+		t.Errorf("missing tests = %v, want none since tests changed", r.MissingTests)
+	}
+}
@@ -0,0 +1,192 @@
+// Package gitlab gathers repository context from GitLab: pipelines and
+// their failed jobs, merge requests and issues. It mirrors the GitHub
+// client, reading the API through the glab CLI so that glab's login and
+// self-managed hosts work the same way gh's do.
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultHost is the GitLab host used when gitlab.host is not set
+const DefaultHost = "gitlab.com"
+
+// Runner runs a glab CLI command and returns its output
+type Runner func(ctx context.Context, args []string) (string, error)
+
+// Client reads one GitLab project
+type Client struct {
+	run          Runner
+	host         string
+	project      string
+	resolvedOnce bool
+}
+
+// NewClient creates a client for project (group/name, with subgroups) on
+// host. An empty token leaves glab to use its own login; an empty host is
+// DefaultHost; an empty project is resolved from the current directory's
+// origin remote.
+func NewClient(token, host, project string) *Client {
+	host = normalizeHost(host)
+	token = strings.TrimSpace(token)
+	return &Client{
+		run: func(ctx context.Context, args []string) (string, error) {
+			return runGlabWithRetry(ctx, token, args)
+		},
+		host:    host,
+		project: strings.Trim(strings.TrimSpace(project), "/"),
+	}
+}
+
+// NewClientWithRunner creates a client that reads GitLab through run
+func NewClientWithRunner(run Runner, host, project string) *Client {
+	return &Client{run: run, host: normalizeHost(host), project: strings.Trim(strings.TrimSpace(project), "/")}
+}
+
+// Host returns the GitLab host the client reads
+func (c *Client) Host() string {
+	return c.host
+}
+
+// ExecCLI runs a glab CLI command against the client's host
+func (c *Client) ExecCLI(ctx context.Context, args []string) (string, error) {
+	return c.run(ctx, args)
+}
+
+func normalizeHost(host string) string {
+	host = strings.TrimSpace(host)
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	host = strings.TrimSuffix(host, "/")
+	if host == "" {
+		return DefaultHost
+	}
+	return host
+}
+
+func runGlabWithRetry(ctx context.Context, token string, args []string) (string, error) {
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		cmd := exec.CommandContext(ctx, "glab", args...)
+		cmd.Env = os.Environ()
+		if token != "" {
+			cmd.Env = append(cmd.Env, "GITLAB_TOKEN="+token)
+		}
+		cmd.Env = append(cmd.Env, "NO_PROMPT=1")
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			return strings.TrimSpace(string(output)), nil
+		}
+		lastErr = fmt.Errorf("glab %s failed: %w (%s)", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		message := strings.ToLower(string(output))
+		if !strings.Contains(message, "502") && !strings.Contains(message, "bad gateway") && !strings.Contains(message, "503") {
+			break
+		}
+		time.Sleep(time.Duration(attempt+1) * 300 * time.Millisecond)
+	}
+	return "", lastErr
+}
+
+// API reads endpoint with glab api and decodes the JSON answer into v
+func (c *Client) API(ctx context.Context, v interface{}, endpoint string) error {
+	output, err := c.run(ctx, []string{"api", endpoint, "--hostname", c.host})
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(output), v); err != nil {
+		return fmt.Errorf("failed to parse glab api %s output: %w", endpoint, err)
+	}
+	return nil
+}
+
+// ProjectPath returns the URL-encoded project path used in API endpoints
+func ProjectPath(project string) string {
+	return url.PathEscape(project)
+}
+
+// scpRemoteRe matches the scp form of a remote, git@host:group/project.git
+var scpRemoteRe = regexp.MustCompile(`^(?:[\w.-]+@)?([\w.-]+):(.+)$`)
+
+// ParseRemoteURL reads the host and project path of a git remote URL in
+// https, ssh or scp form
+func ParseRemoteURL(remote string) (host, project string, ok bool) {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return "", "", false
+	}
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil || u.Hostname() == "" {
+			return "", "", false
+		}
+		host, project = u.Hostname(), u.Path
+	} else if m := scpRemoteRe.FindStringSubmatch(remote); m != nil {
+		host, project = m[1], m[2]
+	} else {
+		return "", "", false
+	}
+	project = strings.TrimSuffix(strings.Trim(project, "/"), ".git")
+	if !strings.Contains(project, "/") {
+		return "", "", false
+	}
+	return host, project, true
+}
+
+// IsGitLabHost reports whether host is gitlab.com, the configured
+// gitlab.host, or a self-managed host named after GitLab
+func IsGitLabHost(host string) bool {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" {
+		return false
+	}
+	if configured := viper.GetString("gitlab.host"); configured != "" && strings.EqualFold(host, normalizeHost(configured)) {
+		return true
+	}
+	return strings.Contains(host, "gitlab")
+}
+
+// DetectRemote reads the current directory's origin remote and returns its
+// host and project when it is hosted on GitLab
+func DetectRemote(ctx context.Context) (host, project string, ok bool) {
+	out, err := exec.CommandContext(ctx, "git", "remote", "get-url", "origin").Output()
+	if err != nil {
+		return "", "", false
+	}
+	host, project, ok = ParseRemoteURL(string(out))
+	if !ok || !IsGitLabHost(host) {
+		return "", "", false
+	}
+	return host, project, true
+}
+
+// ResolveProject returns the configured project, or the one the current
+// directory's origin remote points at
+func (c *Client) ResolveProject(ctx context.Context) (string, error) {
+	if c.project != "" {
+		return c.project, nil
+	}
+	if c.resolvedOnce {
+		return "", fmt.Errorf("unable to resolve the current GitLab project")
+	}
+	c.resolvedOnce = true
+	host, project, ok := DetectRemote(ctx)
+	if !ok {
+		return "", fmt.Errorf("set gitlab.project or run from a clone of a GitLab project")
+	}
+	c.project = project
+	if c.host == DefaultHost {
+		c.host = host
+	}
+	return c.project, nil
+}
@@ -0,0 +1,84 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeGlab answers glab api calls from canned JSON keyed by endpoint
+type fakeGlab struct {
+	responses map[string]string
+	calls     []string
+}
+
+func (f *fakeGlab) run(ctx context.Context, args []string) (string, error) {
+	if len(args) < 4 || args[0] != "api" || args[2] != "--hostname" {
+		return "", errors.New("unexpected glab " + strings.Join(args, " "))
+	}
+	f.calls = append(f.calls, args[1])
+	if out, ok := f.responses[args[1]]; ok {
+		return out, nil
+	}
+	return "", errors.New("glab api " + args[1] + " failed: 404 Not Found")
+}
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		remote, host, project string
+		ok                    bool
+	}{
+		{"https://gitlab.com/acme/shop.git", "gitlab.com", "acme/shop", true},
+		{"git@gitlab.com:acme/platform/api.git\n", "gitlab.com", "acme/platform/api", true},
+		{"ssh://git@gitlab.acme.dev:2222/acme/shop.git", "gitlab.acme.dev", "acme/shop", true},
+		{"https://github.com/acme/shop", "github.com", "acme/shop", true},
+		{"https://gitlab.com/acme", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		host, project, ok := ParseRemoteURL(tt.remote)
+		if host != tt.host || project != tt.project || ok != tt.ok {
+			t.Errorf("ParseRemoteURL(%q) = %q, %q, %v", tt.remote, host, project, ok)
+		}
+	}
+	if !IsGitLabHost("gitlab.com") || !IsGitLabHost("gitlab.acme.dev") || IsGitLabHost("github.com") {
+		t.Error("IsGitLabHost misclassified a host")
+	}
+}
+
+func TestGetRelevantContext(t *testing.T) {
+	glab := &fakeGlab{responses: map[string]string{
+		"projects/acme%2Fshop": `{"path_with_namespace": "acme/shop", "default_branch": "main", "visibility": "private", "web_url": "https://gitlab.com/acme/shop"}`,
+		"projects/acme%2Fshop/pipelines?order_by=id&per_page=15&sort=desc": `[
+			{"id": 1002, "status": "failed", "ref": "main", "sha": "4f2a9c1d0e8b7a6f", "source": "push", "web_url": "https://gitlab.com/acme/shop/-/pipelines/1002"},
+			{"id": 1001, "status": "success", "ref": "main", "sha": "0e8b7a6f4f2a9c1d", "source": "push", "web_url": "https://gitlab.com/acme/shop/-/pipelines/1001"}]`,
+		"projects/acme%2Fshop/pipelines/1002/jobs?per_page=100&scope%5B%5D=failed": `[
+			{"id": 77, "name": "unit", "stage": "test", "status": "failed", "failure_reason": "script_failure", "web_url": "https://gitlab.com/acme/shop/-/jobs/77"}]`,
+		"projects/acme%2Fshop/merge_requests?state=opened&order_by=updated_at&per_page=15": `[
+			{"iid": 12, "title": "Add retries", "source_branch": "retries", "target_branch": "main", "detailed_merge_status": "ci_must_pass", "author": {"username": "dana"}, "web_url": "https://gitlab.com/acme/shop/-/merge_requests/12"}]`,
+	}}
+	client := NewClientWithRunner(glab.run, "", "acme/shop")
+
+	out, err := client.GetRelevantContext(context.Background(), "why is the main pipeline failing")
+	if err != nil {
+		t.Fatalf("GetRelevantContext: %v", err)
+	}
+	for _, want := range []string{
+		"GitLab Project: acme/shop (private, default branch main)",
+		"- #1002 failed on main (4f2a9c1d, push",
+		"Failed jobs of pipeline 1002 (main):\n  - unit (stage test, job 77): failed, script_failure",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("context missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Merge Requests") {
+		t.Errorf("pipeline question should not list merge requests:\n%s", out)
+	}
+
+	out, err = client.GetRelevantContext(context.Background(), "what merge requests are open")
+	if err != nil || !strings.Contains(out, "- !12 Add retries by dana (retries -> main, ci_must_pass)") {
+		t.Errorf("merge request context = %v:\n%s", err, out)
+	}
+}
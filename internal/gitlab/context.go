@@ -0,0 +1,356 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Project is the project a client reads
+type Project struct {
+	Path          string `json:"path_with_namespace"`
+	DefaultBranch string `json:"default_branch"`
+	Visibility    string `json:"visibility"`
+	WebURL        string `json:"web_url"`
+	Archived      bool   `json:"archived"`
+}
+
+// Pipeline is one CI pipeline run
+type Pipeline struct {
+	ID        int64     `json:"id"`
+	IID       int64     `json:"iid"`
+	Status    string    `json:"status"`
+	Ref       string    `json:"ref"`
+	SHA       string    `json:"sha"`
+	Source    string    `json:"source"`
+	WebURL    string    `json:"web_url"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Job is one job of a pipeline
+type Job struct {
+	ID            int64   `json:"id"`
+	Name          string  `json:"name"`
+	Stage         string  `json:"stage"`
+	Status        string  `json:"status"`
+	FailureReason string  `json:"failure_reason"`
+	AllowFailure  bool    `json:"allow_failure"`
+	Duration      float64 `json:"duration"`
+	WebURL        string  `json:"web_url"`
+	Ref           string  `json:"ref"`
+	Pipeline      struct {
+		ID int64 `json:"id"`
+	} `json:"pipeline"`
+}
+
+// MergeRequest is an open merge request
+type MergeRequest struct {
+	IID                 int64     `json:"iid"`
+	Title               string    `json:"title"`
+	SourceBranch        string    `json:"source_branch"`
+	TargetBranch        string    `json:"target_branch"`
+	Draft               bool      `json:"draft"`
+	DetailedMergeStatus string    `json:"detailed_merge_status"`
+	HasConflicts        bool      `json:"has_conflicts"`
+	WebURL              string    `json:"web_url"`
+	UpdatedAt           time.Time `json:"updated_at"`
+	Author              struct {
+		Username string `json:"username"`
+	} `json:"author"`
+}
+
+// Issue is an open issue
+type Issue struct {
+	IID       int64     `json:"iid"`
+	Title     string    `json:"title"`
+	Labels    []string  `json:"labels"`
+	WebURL    string    `json:"web_url"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Assignees []struct {
+		Username string `json:"username"`
+	} `json:"assignees"`
+}
+
+// failedDetailPipelines caps the failed pipelines whose jobs are listed
+const failedDetailPipelines = 3
+
+// GetProject reads the project
+func (c *Client) GetProject(ctx context.Context) (Project, error) {
+	project, err := c.ResolveProject(ctx)
+	if err != nil {
+		return Project{}, err
+	}
+	var p Project
+	if err := c.API(ctx, &p, "projects/"+ProjectPath(project)); err != nil {
+		return Project{}, fmt.Errorf("failed to read project %s: %w", project, err)
+	}
+	return p, nil
+}
+
+// ListPipelines returns the newest pipelines, optionally only those on ref
+// or in status
+func (c *Client) ListPipelines(ctx context.Context, ref, status string, limit int) ([]Pipeline, error) {
+	project, err := c.ResolveProject(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"per_page": {fmt.Sprint(limit)}, "order_by": {"id"}, "sort": {"desc"}}
+	if ref != "" {
+		query.Set("ref", ref)
+	}
+	if status != "" {
+		query.Set("status", status)
+	}
+	var pipelines []Pipeline
+	if err := c.API(ctx, &pipelines, fmt.Sprintf("projects/%s/pipelines?%s", ProjectPath(project), query.Encode())); err != nil {
+		return nil, fmt.Errorf("failed to list pipelines: %w", err)
+	}
+	return pipelines, nil
+}
+
+// GetPipeline reads one pipeline
+func (c *Client) GetPipeline(ctx context.Context, id int64) (Pipeline, error) {
+	project, err := c.ResolveProject(ctx)
+	if err != nil {
+		return Pipeline{}, err
+	}
+	var p Pipeline
+	if err := c.API(ctx, &p, fmt.Sprintf("projects/%s/pipelines/%d", ProjectPath(project), id)); err != nil {
+		return Pipeline{}, fmt.Errorf("failed to read pipeline %d of %s: %w", id, project, err)
+	}
+	return p, nil
+}
+
+// GetJob reads one job
+func (c *Client) GetJob(ctx context.Context, id int64) (Job, error) {
+	project, err := c.ResolveProject(ctx)
+	if err != nil {
+		return Job{}, err
+	}
+	var j Job
+	if err := c.API(ctx, &j, fmt.Sprintf("projects/%s/jobs/%d", ProjectPath(project), id)); err != nil {
+		return Job{}, fmt.Errorf("failed to read job %d of %s: %w", id, project, err)
+	}
+	return j, nil
+}
+
+// ListPipelineJobs returns the jobs of a pipeline, optionally only those in
+// scope (e.g. failed)
+func (c *Client) ListPipelineJobs(ctx context.Context, pipelineID int64, scope string) ([]Job, error) {
+	project, err := c.ResolveProject(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"per_page": {"100"}}
+	if scope != "" {
+		query.Set("scope[]", scope)
+	}
+	var jobs []Job
+	if err := c.API(ctx, &jobs, fmt.Sprintf("projects/%s/pipelines/%d/jobs?%s", ProjectPath(project), pipelineID, query.Encode())); err != nil {
+		return nil, fmt.Errorf("failed to list jobs of pipeline %d: %w", pipelineID, err)
+	}
+	return jobs, nil
+}
+
+// ListMergeRequests returns the most recently updated open merge requests
+func (c *Client) ListMergeRequests(ctx context.Context, limit int) ([]MergeRequest, error) {
+	project, err := c.ResolveProject(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var mrs []MergeRequest
+	endpoint := fmt.Sprintf("projects/%s/merge_requests?state=opened&order_by=updated_at&per_page=%d", ProjectPath(project), limit)
+	if err := c.API(ctx, &mrs, endpoint); err != nil {
+		return nil, fmt.Errorf("failed to list merge requests: %w", err)
+	}
+	return mrs, nil
+}
+
+// ListIssues returns the most recently updated open issues
+func (c *Client) ListIssues(ctx context.Context, limit int) ([]Issue, error) {
+	project, err := c.ResolveProject(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var issues []Issue
+	endpoint := fmt.Sprintf("projects/%s/issues?state=opened&order_by=updated_at&per_page=%d", ProjectPath(project), limit)
+	if err := c.API(ctx, &issues, endpoint); err != nil {
+		return nil, fmt.Errorf("failed to list issues: %w", err)
+	}
+	return issues, nil
+}
+
+func containsAnyPhrase(input string, phrases ...string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(input, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRelevantContext gathers the project's context for question: pipelines
+// and their failed jobs, merge requests and issues, as the question asks.
+// A question that names none of them gets pipelines and merge requests.
+func (c *Client) GetRelevantContext(ctx context.Context, question string) (string, error) {
+	project, err := c.GetProject(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := strings.ToLower(question)
+	includePipelines := containsAnyPhrase(q, "pipeline", "ci", "build", "job", "deploy", "run", "status", "fail", "stage")
+	includeFailedJobs := containsAnyPhrase(q, "fail", "error", "broken", "job", "log", "debug", "why")
+	includeMRs := containsAnyPhrase(q, "merge request", " mr", "mrs", "review", "pull", " pr")
+	includeIssues := containsAnyPhrase(q, "issue", "bug", "ticket", "backlog", "incident")
+	if !includePipelines && !includeMRs && !includeIssues {
+		includePipelines, includeMRs = true, true
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "GitLab Project: %s (%s, default branch %s)", project.Path, project.Visibility, project.DefaultBranch)
+	if project.Archived {
+		sb.WriteString(" [archived]")
+	}
+	fmt.Fprintf(&sb, "\n%s\n\n", project.WebURL)
+
+	if includePipelines {
+		pipelines, err := c.ListPipelines(ctx, "", "", 15)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString("Recent Pipelines:\n")
+		sb.WriteString(FormatPipelines(pipelines))
+		sb.WriteString("\n")
+
+		if includeFailedJobs {
+			detailed := 0
+			for _, p := range pipelines {
+				if p.Status != "failed" || detailed == failedDetailPipelines {
+					continue
+				}
+				detailed++
+				jobs, err := c.ListPipelineJobs(ctx, p.ID, "failed")
+				if err != nil {
+					fmt.Fprintf(&sb, "Failed jobs of pipeline %d: unavailable (%v)\n", p.ID, err)
+					continue
+				}
+				fmt.Fprintf(&sb, "Failed jobs of pipeline %d (%s):\n", p.ID, p.Ref)
+				sb.WriteString(FormatJobs(jobs))
+			}
+			if detailed > 0 {
+				sb.WriteString("\n")
+			}
+		}
+	}
+
+	if includeMRs {
+		mrs, err := c.ListMergeRequests(ctx, 15)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString("Open Merge Requests:\n")
+		sb.WriteString(FormatMergeRequests(mrs))
+		sb.WriteString("\n")
+	}
+
+	if includeIssues {
+		issues, err := c.ListIssues(ctx, 15)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString("Open Issues:\n")
+		sb.WriteString(FormatIssues(issues))
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// FormatPipelines renders pipelines one per line
+func FormatPipelines(pipelines []Pipeline) string {
+	if len(pipelines) == 0 {
+		return "No pipelines found.\n"
+	}
+	var sb strings.Builder
+	for _, p := range pipelines {
+		fmt.Fprintf(&sb, "- #%d %s on %s (%s, %s", p.ID, p.Status, p.Ref, shortSHA(p.SHA), p.Source)
+		if !p.CreatedAt.IsZero() {
+			fmt.Fprintf(&sb, ", %s", p.CreatedAt.UTC().Format(time.RFC3339))
+		}
+		fmt.Fprintf(&sb, ") %s\n", p.WebURL)
+	}
+	return sb.String()
+}
+
+// FormatJobs renders jobs one per line
+func FormatJobs(jobs []Job) string {
+	if len(jobs) == 0 {
+		return "  No failed jobs.\n"
+	}
+	var sb strings.Builder
+	for _, j := range jobs {
+		fmt.Fprintf(&sb, "  - %s (stage %s, job %d): %s", j.Name, j.Stage, j.ID, j.Status)
+		if j.FailureReason != "" {
+			fmt.Fprintf(&sb, ", %s", j.FailureReason)
+		}
+		if j.AllowFailure {
+			sb.WriteString(", allowed to fail")
+		}
+		fmt.Fprintf(&sb, " %s\n", j.WebURL)
+	}
+	return sb.String()
+}
+
+// FormatMergeRequests renders merge requests one per line
+func FormatMergeRequests(mrs []MergeRequest) string {
+	if len(mrs) == 0 {
+		return "No open merge requests.\n"
+	}
+	var sb strings.Builder
+	for _, mr := range mrs {
+		fmt.Fprintf(&sb, "- !%d %s by %s (%s -> %s", mr.IID, mr.Title, mr.Author.Username, mr.SourceBranch, mr.TargetBranch)
+		if mr.Draft {
+			sb.WriteString(", draft")
+		}
+		if mr.DetailedMergeStatus != "" {
+			fmt.Fprintf(&sb, ", %s", mr.DetailedMergeStatus)
+		}
+		if mr.HasConflicts {
+			sb.WriteString(", has conflicts")
+		}
+		fmt.Fprintf(&sb, ") %s\n", mr.WebURL)
+	}
+	return sb.String()
+}
+
+// FormatIssues renders issues one per line
+func FormatIssues(issues []Issue) string {
+	if len(issues) == 0 {
+		return "No open issues.\n"
+	}
+	var sb strings.Builder
+	for _, issue := range issues {
+		fmt.Fprintf(&sb, "- #%d %s", issue.IID, issue.Title)
+		if len(issue.Labels) > 0 {
+			fmt.Fprintf(&sb, " [%s]", strings.Join(issue.Labels, ", "))
+		}
+		if len(issue.Assignees) > 0 {
+			names := make([]string, 0, len(issue.Assignees))
+			for _, a := range issue.Assignees {
+				names = append(names, a.Username)
+			}
+			fmt.Fprintf(&sb, " assigned to %s", strings.Join(names, ", "))
+		}
+		fmt.Fprintf(&sb, " %s\n", issue.WebURL)
+	}
+	return sb.String()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,203 @@
+// Package pipelines plans GitLab CI pipeline and job retries. Plans are
+// built only after GitLab confirms the target exists and has finished, and
+// run glab api against the project's own endpoints when applied.
+package pipelines
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/gitlab"
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// Request is a retry translated from a question or flags
+type Request struct {
+	// Project is group/name when the question names one
+	Project    string
+	PipelineID int64
+	JobID      int64
+	// Ref narrows the latest pipeline when no ID is given
+	Ref string
+}
+
+var (
+	retryRe    = regexp.MustCompile(`(?i)\b(?:retry|re-?run|restart|re-?trigger)\b`)
+	gitlabRe   = regexp.MustCompile(`(?i)\bgitlab\b|/-/(?:pipelines|jobs)/\d+`)
+	targetRe   = regexp.MustCompile(`(?i)\b(?:pipelines?|jobs?)\b`)
+	urlRe      = regexp.MustCompile(`https?://[\w.-]+(?::\d+)?/([\w.-]+(?:/[\w.-]+)+)/-/(pipelines|jobs)/(\d+)`)
+	pipelineRe = regexp.MustCompile(`(?i)\bpipeline\s+#?(\d+)\b`)
+	jobRe      = regexp.MustCompile(`(?i)\bjob\s+#?(\d+)\b`)
+	projectRe  = regexp.MustCompile(`(?i)\b(?:in|project|repo(?:sitory)?)\s+([\w.-]+(?:/[\w.-]+)+)`)
+	refRe      = regexp.MustCompile(`(?i)\b(?:on|branch|ref)\s+(?:branch\s+|ref\s+)?([\w./-]+)`)
+)
+
+// notRefs are words refRe catches that are not branch names
+var notRefs = map[string]bool{"gitlab": true, "the": true, "my": true, "our": true}
+
+// IsRetryQuestion reports whether question asks to retry a GitLab pipeline
+// or job
+func IsRetryQuestion(question string) bool {
+	return retryRe.MatchString(question) && targetRe.MatchString(question) && gitlabRe.MatchString(question)
+}
+
+// ParseRequest reads the pipeline or job a question names
+func ParseRequest(question string) Request {
+	var req Request
+	rest := question
+	if m := urlRe.FindStringSubmatch(rest); m != nil {
+		req.Project = m[1]
+		id, _ := strconv.ParseInt(m[3], 10, 64)
+		if m[2] == "jobs" {
+			req.JobID = id
+		} else {
+			req.PipelineID = id
+		}
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+	if m := projectRe.FindStringSubmatch(rest); m != nil && req.Project == "" {
+		req.Project = strings.TrimSuffix(m[1], ".git")
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+	if m := jobRe.FindStringSubmatch(rest); m != nil && req.JobID == 0 {
+		req.JobID, _ = strconv.ParseInt(m[1], 10, 64)
+	}
+	if m := pipelineRe.FindStringSubmatch(rest); m != nil && req.PipelineID == 0 && req.JobID == 0 {
+		req.PipelineID, _ = strconv.ParseInt(m[1], 10, 64)
+	}
+	if req.PipelineID == 0 && req.JobID == 0 {
+		for _, m := range refRe.FindAllStringSubmatch(rest, -1) {
+			ref := strings.TrimRight(m[1], ".,?!")
+			if !notRefs[strings.ToLower(ref)] {
+				req.Ref = ref
+				break
+			}
+		}
+	}
+	return req
+}
+
+// retriable are the states GitLab retries a pipeline's jobs from
+var retriable = map[string]bool{"failed": true, "canceled": true}
+
+// finished are the job states a job can be retried from
+var finished = map[string]bool{"failed": true, "canceled": true, "success": true}
+
+// Plan confirms req against GitLab and returns the retry plan. Requests
+// that name neither a pipeline nor a job retry the newest pipeline on Ref,
+// or on the default branch.
+func Plan(ctx context.Context, client *gitlab.Client, req Request, question string, now time.Time) (*maker.Plan, error) {
+	project, err := client.GetProject(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if project.Archived {
+		return nil, fmt.Errorf("%s is archived; its pipelines cannot be retried", project.Path)
+	}
+	path := gitlab.ProjectPath(project.Path)
+
+	if req.JobID != 0 {
+		job, err := client.GetJob(ctx, req.JobID)
+		if err != nil {
+			return nil, err
+		}
+		if !finished[job.Status] {
+			return nil, fmt.Errorf("job %d (%s) is %s; only finished jobs can be retried", job.ID, job.Name, job.Status)
+		}
+		summary := fmt.Sprintf("Retry job %s (%d, stage %s, %s) of pipeline %d on %s of %s", job.Name, job.ID, job.Stage, job.Status, job.Pipeline.ID, job.Ref, project.Path)
+		plan := newPlan(question, summary, now)
+		plan.Commands = []maker.Command{{
+			Args:   retryArgs(fmt.Sprintf("projects/%s/jobs/%d/retry", path, job.ID), client.Host()),
+			Reason: fmt.Sprintf("Retry job %s of pipeline %d", job.Name, job.Pipeline.ID),
+		}}
+		plan.Notes = []string{
+			fmt.Sprintf("Confirmed against GitLab: project %s on %s, job %d is %s.", project.Path, client.Host(), job.ID, job.Status),
+			"Retrying creates a new job in the same pipeline; the original job and its log are kept.",
+		}
+		return plan, nil
+	}
+
+	var pipeline gitlab.Pipeline
+	if req.PipelineID != 0 {
+		if pipeline, err = client.GetPipeline(ctx, req.PipelineID); err != nil {
+			return nil, err
+		}
+	} else {
+		ref := firstNonEmpty(req.Ref, project.DefaultBranch)
+		pipelines, err := client.ListPipelines(ctx, ref, "", 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(pipelines) == 0 {
+			return nil, fmt.Errorf("%s has no pipelines on %s", project.Path, ref)
+		}
+		pipeline = pipelines[0]
+	}
+	if !retriable[pipeline.Status] {
+		return nil, fmt.Errorf("pipeline %d on %s is %s; only failed or canceled pipelines can be retried", pipeline.ID, pipeline.Ref, pipeline.Status)
+	}
+
+	jobs, err := client.ListPipelineJobs(ctx, pipeline.ID, pipeline.Status)
+	if err != nil {
+		return nil, err
+	}
+	summary := fmt.Sprintf("Retry the %s jobs of pipeline %d on %s of %s", pipeline.Status, pipeline.ID, pipeline.Ref, project.Path)
+	plan := newPlan(question, summary, now)
+	plan.Commands = []maker.Command{{
+		Args:   retryArgs(fmt.Sprintf("projects/%s/pipelines/%d/retry", path, pipeline.ID), client.Host()),
+		Reason: fmt.Sprintf("Retry the %s jobs of pipeline %d", pipeline.Status, pipeline.ID),
+	}}
+	plan.Notes = []string{
+		fmt.Sprintf("Confirmed against GitLab: project %s on %s, pipeline %d on %s at %s is %s.", project.Path, client.Host(), pipeline.ID, pipeline.Ref, shortSHA(pipeline.SHA), pipeline.Status),
+	}
+	if names := jobNames(jobs); names != "" {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("Jobs to retry: %s.", names))
+	}
+	if req.PipelineID == 0 {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("No pipeline was named, so the newest pipeline on %s was used.", pipeline.Ref))
+	}
+	return plan, nil
+}
+
+// retryArgs is the glab api call that retries endpoint
+func retryArgs(endpoint, host string) []string {
+	return []string{"glab", "api", "--method", "POST", endpoint, "--hostname", host}
+}
+
+func newPlan(question, summary string, now time.Time) *maker.Plan {
+	return &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "gitlab",
+		Question:  question,
+		Summary:   summary,
+	}
+}
+
+func jobNames(jobs []gitlab.Job) string {
+	names := make([]string, 0, len(jobs))
+	for _, j := range jobs {
+		names = append(names, fmt.Sprintf("%s (%s)", j.Name, j.Stage))
+	}
+	return strings.Join(names, ", ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,100 @@
+package pipelines
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/gitlab"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+func fakeGlab(responses map[string]string) gitlab.Runner {
+	return func(ctx context.Context, args []string) (string, error) {
+		if out, ok := responses[args[1]]; ok {
+			return out, nil
+		}
+		return "", errors.New("glab api " + args[1] + " failed: 404 Not Found")
+	}
+}
+
+func newTestClient() *gitlab.Client {
+	return gitlab.NewClientWithRunner(fakeGlab(map[string]string{
+		"projects/acme%2Fshop": `{"path_with_namespace": "acme/shop", "default_branch": "main"}`,
+		"projects/acme%2Fshop/pipelines?order_by=id&per_page=1&ref=main&sort=desc":    `[{"id": 1002, "status": "failed", "ref": "main", "sha": "4f2a9c1d0e8b"}]`,
+		"projects/acme%2Fshop/pipelines?order_by=id&per_page=1&ref=release&sort=desc": `[{"id": 990, "status": "running", "ref": "release"}]`,
+		"projects/acme%2Fshop/pipelines/1002/jobs?per_page=100&scope%5B%5D=failed":    `[{"id": 77, "name": "unit", "stage": "test", "status": "failed"}]`,
+		"projects/acme%2Fshop/jobs/77": `{"id": 77, "name": "unit", "stage": "test", "status": "failed", "ref": "main", "pipeline": {"id": 1002}}`,
+		"projects/acme%2Fshop/jobs/78": `{"id": 78, "name": "deploy", "stage": "deploy", "status": "running", "ref": "main", "pipeline": {"id": 1002}}`,
+	}), "gitlab.com", "acme/shop")
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     Request
+	}{
+		{"retry the failed gitlab pipeline on main", Request{Ref: "main"}},
+		{"retry gitlab pipeline 1002 in acme/shop", Request{Project: "acme/shop", PipelineID: 1002}},
+		{"rerun https://gitlab.com/acme/platform/api/-/jobs/77", Request{Project: "acme/platform/api", JobID: 77}},
+		{"retry job 77 of pipeline 1002 on gitlab", Request{JobID: 77}},
+	}
+	for _, tt := range tests {
+		if got := ParseRequest(tt.question); got != tt.want {
+			t.Errorf("ParseRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestIsRetryQuestion(t *testing.T) {
+	for _, q := range []string{"retry the failed gitlab pipeline on main", "rerun https://gitlab.com/acme/shop/-/jobs/77"} {
+		if !IsRetryQuestion(q) {
+			t.Errorf("IsRetryQuestion(%q) = false", q)
+		}
+	}
+	for _, q := range []string{"re-run the failed jobs of run 9876543210", "why did the gitlab pipeline fail", "retry the deploy on gitlab"} {
+		if IsRetryQuestion(q) {
+			t.Errorf("IsRetryQuestion(%q) = true", q)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	client := newTestClient()
+	ctx := context.Background()
+
+	plan, err := Plan(ctx, client, Request{}, "q", testNow)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	want := []string{"glab", "api", "--method", "POST", "projects/acme%2Fshop/pipelines/1002/retry", "--hostname", "gitlab.com"}
+	if plan.Provider != "gitlab" || len(plan.Commands) != 1 || !reflect.DeepEqual(plan.Commands[0].Args, want) {
+		t.Fatalf("plan = %s %+v", plan.Provider, plan.Commands)
+	}
+	if plan.Summary != "Retry the failed jobs of pipeline 1002 on main of acme/shop" || !strings.Contains(strings.Join(plan.Notes, "\n"), "Jobs to retry: unit (test).") {
+		t.Errorf("summary = %q, notes = %v", plan.Summary, plan.Notes)
+	}
+
+	plan, err = Plan(ctx, client, Request{JobID: 77}, "q", testNow)
+	if err != nil {
+		t.Fatalf("Plan job: %v", err)
+	}
+	if got := plan.Commands[0].Args[4]; got != "projects/acme%2Fshop/jobs/77/retry" {
+		t.Errorf("job endpoint = %s", got)
+	}
+
+	for req, wantErr := range map[Request]string{
+		{Ref: "release"}:      "pipeline 990 on release is running; only failed or canceled pipelines can be retried",
+		{JobID: 78}:           "job 78 (deploy) is running; only finished jobs can be retried",
+		{PipelineID: 555}:     "failed to read pipeline 555 of acme/shop",
+		{Ref: "feature/nope"}: "failed to list pipelines",
+	} {
+		if _, err := Plan(ctx, client, req, "q", testNow); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Plan(%+v) error = %v, want %q", req, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Package hooks runs user-defined scripts when clanker generates or applies
+// a plan, or produces a report. Hooks are configured per event in the
+// config file:
+//
+//	hooks:
+//	  timeout: 30s
+//	  plan_generated:
+//	    - ~/.clanker/hooks/policy-check.sh
+//	  plan_applied:
+//	    - ./notify-slack.sh
+//	  report_generated:
+//	    - python3 ~/bin/open-ticket.py
+//
+// Each command runs through `sh -c` with a JSON Payload on stdin and
+// CLANKER_HOOK_EVENT / CLANKER_HOOK_SOURCE set. Hook output goes to stderr
+// so it never mixes with plan or report JSON on stdout.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Event identifies when a hook fires
+type Event string
+
+const (
+	EventPlanGenerated   Event = "plan_generated"
+	EventPlanApplied     Event = "plan_applied"
+	EventReportGenerated Event = "report_generated"
+)
+
+// defaultTimeout bounds each hook unless hooks.timeout is set
+const defaultTimeout = 30 * time.Second
+
+// Payload is the JSON document written to a hook's stdin
+type Payload struct {
+	Event     Event           `json:"event"`
+	Source    string          `json:"source"` // command that produced the data, e.g. "ask --maker"
+	Timestamp time.Time       `json:"timestamp"`
+	Success   *bool           `json:"success,omitempty"` // plan_applied only
+	Error     string          `json:"error,omitempty"`   // plan_applied failure message
+	Data      json.RawMessage `json:"data"`
+}
+
+// Runner executes the hooks configured for an event
+type Runner struct {
+	Commands map[Event][]string
+	Timeout  time.Duration
+	Output   io.Writer // receives hook stdout and stderr
+}
+
+// FromConfig builds a Runner from the hooks section of the config file
+func FromConfig() *Runner {
+	r := &Runner{
+		Commands: map[Event][]string{},
+		Timeout:  viper.GetDuration("hooks.timeout"),
+		Output:   os.Stderr,
+	}
+	for _, event := range []Event{EventPlanGenerated, EventPlanApplied, EventReportGenerated} {
+		for _, command := range viper.GetStringSlice("hooks." + string(event)) {
+			if strings.TrimSpace(command) != "" {
+				r.Commands[event] = append(r.Commands[event], command)
+			}
+		}
+	}
+	return r
+}
+
+// Run sends payload to every hook registered for its event, in order. All
+// hooks run even if one fails; the returned error names each failure.
+func (r *Runner) Run(ctx context.Context, payload Payload) error {
+	commands := r.Commands[payload.Event]
+	if len(commands) == 0 {
+		return nil
+	}
+	if payload.Timestamp.IsZero() {
+		payload.Timestamp = time.Now().UTC()
+	}
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s hook payload: %w", payload.Event, err)
+	}
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	output := r.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	var errs []error
+	for _, command := range commands {
+		hookCtx, cancel := context.WithTimeout(ctx, timeout)
+		cmd := exec.CommandContext(hookCtx, "sh", "-c", command)
+		cmd.Stdin = bytes.NewReader(input)
+		cmd.Stdout = output
+		cmd.Stderr = output
+		// Children of sh can hold the output pipe open after a timeout kill
+		cmd.WaitDelay = time.Second
+		cmd.Env = append(os.Environ(),
+			"CLANKER_HOOK_EVENT="+string(payload.Event),
+			"CLANKER_HOOK_SOURCE="+payload.Source,
+		)
+		runErr := cmd.Run()
+		if hookCtx.Err() == context.DeadlineExceeded {
+			runErr = fmt.Errorf("timed out after %s", timeout)
+		}
+		cancel()
+		if runErr != nil {
+			errs = append(errs, fmt.Errorf("%s hook %q failed: %w", payload.Event, command, runErr))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run fires the configured hooks for event with data as the payload body.
+// data may be pre-encoded JSON ([]byte or json.RawMessage) or any value
+// that marshals to JSON.
+func Run(ctx context.Context, event Event, source string, data interface{}) error {
+	r := FromConfig()
+	if len(r.Commands[event]) == 0 {
+		return nil
+	}
+	raw, err := encodeData(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s hook data: %w", event, err)
+	}
+	return r.Run(ctx, Payload{Event: event, Source: source, Data: raw})
+}
+
+// RunApplied fires plan_applied hooks with the plan and the apply outcome
+func RunApplied(ctx context.Context, source string, plan []byte, applyErr error) error {
+	r := FromConfig()
+	if len(r.Commands[EventPlanApplied]) == 0 {
+		return nil
+	}
+	raw, err := encodeData(plan)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s hook data: %w", EventPlanApplied, err)
+	}
+	success := applyErr == nil
+	payload := Payload{Event: EventPlanApplied, Source: source, Success: &success, Data: raw}
+	if applyErr != nil {
+		payload.Error = applyErr.Error()
+	}
+	return r.Run(ctx, payload)
+}
+
+func encodeData(data interface{}) (json.RawMessage, error) {
+	switch v := data.(type) {
+	case json.RawMessage:
+		if json.Valid(v) {
+			return v, nil
+		}
+		return json.Marshal(string(v))
+	case []byte:
+		if json.Valid(v) {
+			return json.RawMessage(v), nil
+		}
+		return json.Marshal(string(v))
+	default:
+		return json.Marshal(v)
+	}
+}
@@ -0,0 +1,92 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestRunnerPassesPayloadOnStdin(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "payload.json")
+	var output bytes.Buffer
+	r := &Runner{
+		Commands: map[Event][]string{
+			EventPlanGenerated: {"cat > " + out + "; echo \"event=$CLANKER_HOOK_EVENT source=$CLANKER_HOOK_SOURCE\""},
+		},
+		Output: &output,
+	}
+
+	err := r.Run(context.Background(), Payload{Event: EventPlanGenerated, Source: "ask --maker", Data: json.RawMessage(`{"commands":[]}`)})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := output.String(); got != "event=plan_generated source=ask --maker\n" {
+		t.Errorf("hook output = %q", got)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload Payload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v\n%s", err, data)
+	}
+	if payload.Event != EventPlanGenerated || string(payload.Data) != `{"commands":[]}` || payload.Timestamp.IsZero() {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestRunnerReportsEveryFailure(t *testing.T) {
+	r := &Runner{
+		Commands: map[Event][]string{EventReportGenerated: {"exit 3", "true", "sleep 5"}},
+		Timeout:  200 * time.Millisecond,
+		Output:   &bytes.Buffer{},
+	}
+
+	err := r.Run(context.Background(), Payload{Event: EventReportGenerated, Data: json.RawMessage(`{}`)})
+	if err == nil {
+		t.Fatal("expected error from failing hooks")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, `"exit 3"`) || !strings.Contains(msg, "timed out") || strings.Contains(msg, `"true"`) {
+		t.Errorf("error = %q", msg)
+	}
+}
+
+func TestRunAppliedFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "applied.json")
+	viper.Set("hooks.plan_applied", []string{"cat > " + out})
+	t.Cleanup(func() { viper.Set("hooks.plan_applied", nil) })
+
+	if err := RunApplied(context.Background(), "ask --apply", []byte("not json"), errors.New("step 2 failed")); err != nil {
+		t.Fatalf("RunApplied: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload Payload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Success == nil || *payload.Success || payload.Error != "step 2 failed" || string(payload.Data) != `"not json"` {
+		t.Errorf("payload = %+v data=%s", payload, payload.Data)
+	}
+}
+
+func TestRunWithoutHooksIsNoop(t *testing.T) {
+	if err := Run(context.Background(), EventReportGenerated, "k8s cost", make(chan int)); err != nil {
+		t.Errorf("unconfigured hooks should not encode data: %v", err)
+	}
+}
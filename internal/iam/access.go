@@ -0,0 +1,218 @@
+package iam
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ServicePermissions are the actions a user's policies allow and deny in
+// one service
+type ServicePermissions struct {
+	Service string   `json:"service"`
+	Allowed []string `json:"allowed,omitempty"`
+	Denied  []string `json:"denied,omitempty"`
+	// AllResources is set when an allow applies to every resource
+	AllResources bool `json:"all_resources,omitempty"`
+	// Conditional is set when every allow in the service has a condition
+	Conditional bool `json:"conditional,omitempty"`
+	// Sources name the policies that grant or deny the actions
+	Sources []string `json:"sources"`
+}
+
+// policySource is a policy document and where the user gets it from
+type policySource struct {
+	label    string
+	document string
+}
+
+// sources lists every policy that applies to the user, labelled with where
+// it comes from
+func (a *UserAccess) sources() []policySource {
+	var out []policySource
+	for _, p := range a.AttachedPolicies {
+		out = append(out, policySource{label: p.PolicyName, document: a.Documents[p.PolicyARN]})
+	}
+	for _, p := range a.InlinePolicies {
+		out = append(out, policySource{label: p.PolicyName + " (inline)", document: p.PolicyDocument})
+	}
+	for _, g := range a.Groups {
+		for _, p := range g.AttachedPolicies {
+			out = append(out, policySource{label: p.PolicyName + " (group " + g.GroupName + ")", document: a.Documents[p.PolicyARN]})
+		}
+		for _, p := range g.InlinePolicies {
+			out = append(out, policySource{label: p.PolicyName + " (group " + g.GroupName + ", inline)", document: p.PolicyDocument})
+		}
+	}
+	return out
+}
+
+// EffectivePermissions summarizes what the user's policies allow and deny,
+// by service. Explicit denies are listed but not subtracted, and conditions
+// and resource scoping are flagged rather than evaluated. Policies whose
+// documents could not be read are returned as unreadable.
+func (a *UserAccess) EffectivePermissions() (services []ServicePermissions, unreadable []string) {
+	type entry struct {
+		allowed, denied, sources map[string]bool
+		allResources             bool
+		unconditional            bool
+		anyAllow                 bool
+	}
+	byService := make(map[string]*entry)
+	get := func(service string) *entry {
+		if e, ok := byService[service]; ok {
+			return e
+		}
+		e := &entry{allowed: map[string]bool{}, denied: map[string]bool{}, sources: map[string]bool{}}
+		byService[service] = e
+		return e
+	}
+
+	for _, src := range a.sources() {
+		doc, err := ParsePolicyDocument(src.document)
+		if src.document == "" || err != nil {
+			unreadable = append(unreadable, src.label)
+			continue
+		}
+		for _, stmt := range doc.Statement {
+			allow := stmt.Effect == "Allow"
+			actions := toStringSlice(stmt.Action)
+			if notActions := toStringSlice(stmt.NotAction); len(notActions) > 0 {
+				actions = []string{"* except " + strings.Join(notActions, ", ")}
+			}
+			allResources := false
+			for _, r := range toStringSlice(stmt.Resource) {
+				if r == "*" {
+					allResources = true
+				}
+			}
+			for _, action := range actions {
+				e := get(actionService(action))
+				e.sources[src.label] = true
+				if !allow {
+					e.denied[action] = true
+					continue
+				}
+				e.allowed[action] = true
+				e.anyAllow = true
+				e.allResources = e.allResources || allResources
+				e.unconditional = e.unconditional || stmt.Condition == nil
+			}
+		}
+	}
+
+	for service, e := range byService {
+		services = append(services, ServicePermissions{
+			Service:      service,
+			Allowed:      collapseActions(e.allowed),
+			Denied:       collapseActions(e.denied),
+			AllResources: e.allResources,
+			Conditional:  e.anyAllow && !e.unconditional,
+			Sources:      sortedKeys(e.sources),
+		})
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Service < services[j].Service })
+	return services, unreadable
+}
+
+// actionService is the service prefix of an action; "*" and NotAction
+// grants span every service
+func actionService(action string) string {
+	if i := strings.Index(action, ":"); i > 0 && !strings.HasPrefix(action, "* except") {
+		return strings.ToLower(action[:i])
+	}
+	return "*"
+}
+
+// collapseActions returns the sorted actions, keeping only the service
+// wildcard when one is present
+func collapseActions(actions map[string]bool) []string {
+	for action := range actions {
+		if action == "*" || strings.HasSuffix(action, ":*") {
+			return []string{action}
+		}
+	}
+	return sortedKeys(actions)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatUserAccess lists where a user's policies come from, then the
+// effective permissions they add up to
+func formatUserAccess(access *UserAccess) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Policies for user %s:\n", access.UserName))
+
+	sb.WriteString("\nAttached Managed Policies:\n")
+	if len(access.AttachedPolicies) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for _, p := range access.AttachedPolicies {
+		sb.WriteString(fmt.Sprintf("  - %s (%s)\n", p.PolicyName, p.PolicyARN))
+	}
+
+	sb.WriteString("\nInline Policies:\n")
+	if len(access.InlinePolicies) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for _, p := range access.InlinePolicies {
+		sb.WriteString(fmt.Sprintf("  - %s\n", p.PolicyName))
+	}
+
+	sb.WriteString("\nGroups:\n")
+	if len(access.Groups) == 0 {
+		sb.WriteString("  (none)\n")
+	}
+	for _, g := range access.Groups {
+		sb.WriteString(fmt.Sprintf("  - %s\n", g.GroupName))
+		for _, p := range g.AttachedPolicies {
+			sb.WriteString(fmt.Sprintf("      %s (%s)\n", p.PolicyName, p.PolicyARN))
+		}
+		for _, p := range g.InlinePolicies {
+			sb.WriteString(fmt.Sprintf("      %s (inline)\n", p.PolicyName))
+		}
+	}
+
+	services, unreadable := access.EffectivePermissions()
+	sb.WriteString("\nEffective Permissions:\n")
+	if len(services) == 0 {
+		sb.WriteString("  No permissions granted\n")
+	}
+	for _, s := range services {
+		if s.Service == "*" && len(s.Allowed) > 0 && s.Allowed[0] == "*" {
+			sb.WriteString("  FULL ADMIN ACCESS (*)")
+		} else if len(s.Allowed) > 0 {
+			sb.WriteString(fmt.Sprintf("  %s: %s", s.Service, strings.Join(s.Allowed, ", ")))
+		} else {
+			sb.WriteString(fmt.Sprintf("  %s:", s.Service))
+		}
+		var flags []string
+		if len(s.Allowed) > 0 && s.AllResources {
+			flags = append(flags, "all resources")
+		}
+		if s.Conditional {
+			flags = append(flags, "conditional")
+		}
+		if len(s.Denied) > 0 {
+			flags = append(flags, "denied: "+strings.Join(s.Denied, ", "))
+		}
+		if len(flags) > 0 {
+			sb.WriteString(" [" + strings.Join(flags, "; ") + "]")
+		}
+		sb.WriteString(fmt.Sprintf("\n      from %s\n", strings.Join(s.Sources, ", ")))
+	}
+	if access.PermissionsBoundary != "" {
+		sb.WriteString(fmt.Sprintf("\nPermissions boundary %s limits the permissions above\n", access.PermissionsBoundary))
+	}
+	if len(unreadable) > 0 {
+		sb.WriteString(fmt.Sprintf("\nNot summarized (document unavailable): %s\n", strings.Join(unreadable, ", ")))
+	}
+	return sb.String()
+}
@@ -0,0 +1,90 @@
+package iam
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testUserAccess() *UserAccess {
+	return &UserAccess{
+		UserName:         "alice",
+		AttachedPolicies: []PolicyInfo{{PolicyName: "ReadOnlyS3", PolicyARN: "arn:aws:iam::123456789012:policy/ReadOnlyS3"}},
+		InlinePolicies: []InlinePolicy{{PolicyName: "deny-iam", PolicyDocument: `{
+			"Version": "2012-10-17",
+			"Statement": [{"Effect": "Deny", "Action": "iam:*", "Resource": "*"}]
+		}`}},
+		Groups: []GroupAccess{{
+			GroupName:        "developers",
+			AttachedPolicies: []PolicyInfo{{PolicyName: "Missing", PolicyARN: "arn:aws:iam::123456789012:policy/Missing"}},
+			InlinePolicies: []InlinePolicy{{PolicyName: "deploy", PolicyDocument: `{
+				"Version": "2012-10-17",
+				"Statement": [
+					{"Effect": "Allow", "Action": ["s3:PutObject", "s3:GetObject"], "Resource": "arn:aws:s3:::assets/*"},
+					{"Effect": "Allow", "Action": "lambda:*", "Resource": "*", "Condition": {"StringEquals": {"aws:RequestedRegion": "us-east-1"}}}
+				]
+			}`}},
+		}},
+		Documents: map[string]string{
+			"arn:aws:iam::123456789012:policy/ReadOnlyS3": `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": ["s3:GetObject", "s3:ListBucket"], "Resource": "*"}}`,
+		},
+	}
+}
+
+func TestEffectivePermissions(t *testing.T) {
+	services, unreadable := testUserAccess().EffectivePermissions()
+
+	if !reflect.DeepEqual(unreadable, []string{"Missing (group developers)"}) {
+		t.Errorf("unreadable = %v", unreadable)
+	}
+	want := []ServicePermissions{
+		{Service: "iam", Denied: []string{"iam:*"}, Sources: []string{"deny-iam (inline)"}},
+		{Service: "lambda", Allowed: []string{"lambda:*"}, AllResources: true, Conditional: true, Sources: []string{"deploy (group developers, inline)"}},
+		{
+			Service:      "s3",
+			Allowed:      []string{"s3:GetObject", "s3:ListBucket", "s3:PutObject"},
+			AllResources: true,
+			Sources:      []string{"ReadOnlyS3", "deploy (group developers, inline)"},
+		},
+	}
+	if !reflect.DeepEqual(services, want) {
+		t.Errorf("EffectivePermissions() =\n%+v\nwant\n%+v", services, want)
+	}
+}
+
+func TestEffectivePermissionsAdmin(t *testing.T) {
+	access := &UserAccess{
+		UserName:         "root-ish",
+		AttachedPolicies: []PolicyInfo{{PolicyName: "AdministratorAccess", PolicyARN: "arn:aws:iam::aws:policy/AdministratorAccess"}},
+		Documents: map[string]string{
+			"arn:aws:iam::aws:policy/AdministratorAccess": `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "*", "Resource": "*"}]}`,
+		},
+		PermissionsBoundary: "arn:aws:iam::123456789012:policy/boundary",
+	}
+	out := formatUserAccess(access)
+	for _, want := range []string{
+		"FULL ADMIN ACCESS (*) [all resources]\n      from AdministratorAccess",
+		"Permissions boundary arn:aws:iam::123456789012:policy/boundary limits the permissions above",
+		"Groups:\n  (none)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("formatUserAccess() missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestFormatUserAccess(t *testing.T) {
+	out := formatUserAccess(testUserAccess())
+	for _, want := range []string{
+		"Policies for user alice:",
+		"  - ReadOnlyS3 (arn:aws:iam::123456789012:policy/ReadOnlyS3)",
+		"  - developers\n      Missing (arn:aws:iam::123456789012:policy/Missing)\n      deploy (inline)",
+		"  iam: [denied: iam:*]",
+		"  lambda: lambda:* [all resources; conditional]",
+		"Not summarized (document unavailable): Missing (group developers)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("formatUserAccess() missing %q:\n%s", want, out)
+		}
+	}
+}
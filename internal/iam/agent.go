@@ -5,13 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/bgdnvk/clanker/internal/ai"
 	"github.com/bgdnvk/clanker/internal/iam/analyzer"
 	"github.com/bgdnvk/clanker/internal/iam/fixer"
+	"github.com/bgdnvk/clanker/internal/log"
 	"github.com/spf13/viper"
 )
 
+var logger = log.New("iam")
+
 // Agent orchestrates IAM operations
 type Agent struct {
 	client       *Client
@@ -26,6 +30,9 @@ type AgentOptions struct {
 	Profile string
 	Region  string
 	Debug   bool
+	// CLI runs aws CLI commands for the services the IAM client has no SDK
+	// client for, such as Access Analyzer
+	CLI CLIRunner
 }
 
 // NewAgentWithOptions creates a new IAM agent with the specified options
@@ -35,10 +42,12 @@ func NewAgentWithOptions(opts AgentOptions) (*Agent, error) {
 		return nil, fmt.Errorf("failed to create IAM client: %w", err)
 	}
 
+	client.SetCLI(opts.CLI)
+
 	accountID := client.GetAccountID()
 	conversation := NewConversationHistory(accountID)
-	if err := conversation.Load(); err != nil && opts.Debug {
-		fmt.Printf("[iam] Warning: could not load conversation history: %v\n", err)
+	if err := conversation.Load(); err != nil {
+		logger.Debugf("Warning: could not load conversation history: %v", err)
 	}
 
 	// Create adapters for the subagents
@@ -56,13 +65,16 @@ func NewAgentWithOptions(opts AgentOptions) (*Agent, error) {
 
 // HandleQuery handles an IAM query and returns a response
 func (a *Agent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
-	if a.debug {
-		fmt.Printf("[iam] Processing query: %s\n", query)
-	}
+	logger.Debugf("Processing query: %s", query)
 
 	// Check for special commands
 	queryLower := strings.ToLower(strings.TrimSpace(query))
 
+	// Handle external exposure requests
+	if isExposureQuery(queryLower) {
+		return a.handleExposureRequest(ctx, query)
+	}
+
 	// Handle analyze requests
 	if strings.Contains(queryLower, "analyze") || strings.Contains(queryLower, "security") {
 		return a.handleAnalyzeRequest(ctx, query, opts)
@@ -77,6 +89,43 @@ func (a *Agent) HandleQuery(ctx context.Context, query string, opts QueryOptions
 	return a.handleGeneralQuery(ctx, query, opts)
 }
 
+// exposureKeywords mark questions about access from outside the account
+var exposureKeywords = []string{
+	"access analyzer", "external access", "external exposure", "exposure report",
+	"publicly accessible", "exposed to the internet", "cross-account access",
+}
+
+func isExposureQuery(queryLower string) bool {
+	for _, keyword := range exposureKeywords {
+		if strings.Contains(queryLower, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleExposureRequest reports public and cross-account access from Access
+// Analyzer and the role trust policies, with a plan for what can be fixed
+func (a *Agent) handleExposureRequest(ctx context.Context, query string) (*Response, error) {
+	report, err := a.client.GetExposureReport(ctx)
+	if err != nil {
+		return &Response{
+			Type:  ResponseTypeError,
+			Error: err,
+		}, nil
+	}
+
+	formatted := report.Format()
+	a.conversation.AddEntry(query, formatted, a.client.GetAccountID())
+	_ = a.conversation.Save()
+
+	return &Response{
+		Type:        ResponseTypeFindings,
+		Content:     formatted,
+		Remediation: report.RemediationPlan(query, time.Now()),
+	}, nil
+}
+
 // handleAnalyzeRequest handles security analysis requests
 func (a *Agent) handleAnalyzeRequest(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
 	var analyzerFindings []analyzer.SecurityFinding
@@ -196,9 +245,7 @@ func (a *Agent) handleGeneralQuery(ctx context.Context, query string, opts Query
 	scopedContext := ""
 	if opts.RoleARN != "" {
 		roleName := extractRoleName(opts.RoleARN)
-		if a.debug {
-			fmt.Printf("[iam] Fetching details for scoped role: %s\n", roleName)
-		}
+		logger.Debugf("Fetching details for scoped role: %s", roleName)
 		roleDetail, err := a.client.GetRoleDetails(ctx, roleName)
 		if err == nil {
 			scopedContext = fmt.Sprintf(`
@@ -213,13 +260,11 @@ Last Used: %s
 The user's question should be answered specifically about this role.
 `, roleDetail.RoleName, roleDetail.RoleARN, roleDetail.AssumeRolePolicyDocument,
 				len(roleDetail.AttachedPolicies), len(roleDetail.InlinePolicies), roleDetail.LastUsed)
-		} else if a.debug {
-			fmt.Printf("[iam] Failed to get role details: %v\n", err)
+		} else {
+			logger.Debugf("Failed to get role details: %v", err)
 		}
 	} else if opts.PolicyARN != "" {
-		if a.debug {
-			fmt.Printf("[iam] Fetching details for scoped policy: %s\n", opts.PolicyARN)
-		}
+		logger.Debugf("Fetching details for scoped policy: %s", opts.PolicyARN)
 		policyDetail, err := a.client.GetPolicyDocument(ctx, opts.PolicyARN)
 		if err == nil {
 			scopedContext = fmt.Sprintf(`
@@ -231,8 +276,8 @@ Policy Document:
 
 The user's question should be answered specifically about this policy.
 `, policyDetail.PolicyName, policyDetail.PolicyARN, policyDetail.PolicyDocument)
-		} else if a.debug {
-			fmt.Printf("[iam] Failed to get policy details: %v\n", err)
+		} else {
+			logger.Debugf("Failed to get policy details: %v", err)
 		}
 	}
 
@@ -258,9 +303,7 @@ The user's question should be answered specifically about this policy.
 	analysisResp = aiClient.CleanJSONResponse(analysisResp)
 	var analysis IAMAnalysis
 	if err := json.Unmarshal([]byte(analysisResp), &analysis); err != nil {
-		if a.debug {
-			fmt.Printf("[iam] Failed to parse analysis: %v\nRaw response: %s\n", err, analysisResp)
-		}
+		logger.Debugf("Failed to parse analysis: %v\nRaw response: %s", err, analysisResp)
 		// If parsing fails, return a basic response
 		return &Response{
 			Type:    ResponseTypeResult,
@@ -268,10 +311,8 @@ The user's question should be answered specifically about this policy.
 		}, nil
 	}
 
-	if a.debug {
-		fmt.Printf("[iam] Analysis: %s\n", analysis.Analysis)
-		fmt.Printf("[iam] Operations needed: %d\n", len(analysis.Operations))
-	}
+	logger.Debugf("Analysis: %s", analysis.Analysis)
+	logger.Debugf("Operations needed: %d", len(analysis.Operations))
 
 	// Execute the operations
 	iamData, err := a.client.ExecuteOperations(ctx, analysis.Operations)
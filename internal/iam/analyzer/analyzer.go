@@ -3,8 +3,12 @@ package analyzer
 import (
 	"context"
 	"fmt"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("iam.analyzer")
+
 // IAMClient interface defines the methods needed from the IAM client
 type IAMClient interface {
 	GetRoleDetails(ctx interface{}, roleName string) (*RoleDetail, error)
@@ -30,45 +34,39 @@ func NewSubAgent(client IAMClient, debug bool) *SubAgent {
 
 // AnalyzeAccount performs a comprehensive security analysis of the entire IAM account
 func (a *SubAgent) AnalyzeAccount(ctx context.Context) ([]SecurityFinding, error) {
-	if a.debug {
-		fmt.Println("[iam-analyzer] Starting account-wide security analysis...")
-	}
+	logger.Debug("Starting account-wide security analysis...")
 
 	var findings []SecurityFinding
 
 	// Analyze all roles
 	roleFindings, err := a.analyzeAllRoles(ctx)
-	if err != nil && a.debug {
-		fmt.Printf("[iam-analyzer] Warning: error analyzing roles: %v\n", err)
+	if err != nil {
+		logger.Debugf("Warning: error analyzing roles: %v", err)
 	}
 	findings = append(findings, roleFindings...)
 
 	// Analyze all policies
 	policyFindings, err := a.analyzeAllPolicies(ctx)
-	if err != nil && a.debug {
-		fmt.Printf("[iam-analyzer] Warning: error analyzing policies: %v\n", err)
+	if err != nil {
+		logger.Debugf("Warning: error analyzing policies: %v", err)
 	}
 	findings = append(findings, policyFindings...)
 
 	// Analyze credential report
 	credentialFindings, err := a.analyzeCredentials(ctx)
-	if err != nil && a.debug {
-		fmt.Printf("[iam-analyzer] Warning: error analyzing credentials: %v\n", err)
+	if err != nil {
+		logger.Debugf("Warning: error analyzing credentials: %v", err)
 	}
 	findings = append(findings, credentialFindings...)
 
-	if a.debug {
-		fmt.Printf("[iam-analyzer] Analysis complete. Found %d security issues.\n", len(findings))
-	}
+	logger.Debugf("Analysis complete. Found %d security issues.", len(findings))
 
 	return findings, nil
 }
 
 // AnalyzeRole performs security analysis on a specific role
 func (a *SubAgent) AnalyzeRole(ctx context.Context, roleName string) ([]SecurityFinding, error) {
-	if a.debug {
-		fmt.Printf("[iam-analyzer] Analyzing role: %s\n", roleName)
-	}
+	logger.Debugf("Analyzing role: %s", roleName)
 
 	var findings []SecurityFinding
 
@@ -117,9 +115,7 @@ func (a *SubAgent) AnalyzeRole(ctx context.Context, roleName string) ([]Security
 
 // AnalyzePolicy performs security analysis on a specific policy
 func (a *SubAgent) AnalyzePolicy(ctx context.Context, policyARN string) ([]SecurityFinding, error) {
-	if a.debug {
-		fmt.Printf("[iam-analyzer] Analyzing policy: %s\n", policyARN)
-	}
+	logger.Debugf("Analyzing policy: %s", policyARN)
 
 	detail, err := a.client.GetPolicyDocument(ctx, policyARN)
 	if err != nil {
@@ -142,9 +138,7 @@ func (a *SubAgent) analyzeAllRoles(ctx context.Context) ([]SecurityFinding, erro
 	for _, role := range roles {
 		roleFindings, err := a.AnalyzeRole(ctx, role.RoleName)
 		if err != nil {
-			if a.debug {
-				fmt.Printf("[iam-analyzer] Warning: error analyzing role %s: %v\n", role.RoleName, err)
-			}
+			logger.Debugf("Warning: error analyzing role %s: %v", role.RoleName, err)
 			continue
 		}
 		findings = append(findings, roleFindings...)
@@ -165,9 +159,7 @@ func (a *SubAgent) analyzeAllPolicies(ctx context.Context) ([]SecurityFinding, e
 	for _, policy := range policies {
 		policyFindings, err := a.AnalyzePolicy(ctx, policy.PolicyARN)
 		if err != nil {
-			if a.debug {
-				fmt.Printf("[iam-analyzer] Warning: error analyzing policy %s: %v\n", policy.PolicyName, err)
-			}
+			logger.Debugf("Warning: error analyzing policy %s: %v", policy.PolicyName, err)
 			continue
 		}
 		findings = append(findings, policyFindings...)
@@ -23,6 +23,7 @@ type Client struct {
 	profile   string
 	region    string
 	accountID string
+	cli       CLIRunner
 	debug     bool
 }
 
@@ -393,6 +394,149 @@ func (c *Client) ListAccessKeys(ctx context.Context, userName string) ([]AccessK
 	return keys, nil
 }
 
+// GetUserAccess returns a user's attached and inline policies, the policies
+// of its groups, and the documents of every attached managed policy
+func (c *Client) GetUserAccess(ctx context.Context, userName string) (*UserAccess, error) {
+	userResp, err := c.iam.GetUser(ctx, &iam.GetUserInput{
+		UserName: aws.String(userName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %s: %w", userName, err)
+	}
+
+	access := &UserAccess{
+		UserName:  aws.ToString(userResp.User.UserName),
+		UserARN:   aws.ToString(userResp.User.Arn),
+		Documents: make(map[string]string),
+	}
+	if userResp.User.PermissionsBoundary != nil {
+		access.PermissionsBoundary = aws.ToString(userResp.User.PermissionsBoundary.PermissionsBoundaryArn)
+	}
+
+	// Get attached policies
+	attachedPaginator := iam.NewListAttachedUserPoliciesPaginator(c.iam, &iam.ListAttachedUserPoliciesInput{
+		UserName: aws.String(userName),
+	})
+	for attachedPaginator.HasMorePages() {
+		page, err := attachedPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list attached policies for %s: %w", userName, err)
+		}
+		access.AttachedPolicies = append(access.AttachedPolicies, attachedPolicyInfos(page.AttachedPolicies)...)
+	}
+
+	// Get inline policies
+	inlinePaginator := iam.NewListUserPoliciesPaginator(c.iam, &iam.ListUserPoliciesInput{
+		UserName: aws.String(userName),
+	})
+	for inlinePaginator.HasMorePages() {
+		page, err := inlinePaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list inline policies for %s: %w", userName, err)
+		}
+		for _, policyName := range page.PolicyNames {
+			policyResp, err := c.iam.GetUserPolicy(ctx, &iam.GetUserPolicyInput{
+				UserName:   aws.String(userName),
+				PolicyName: aws.String(policyName),
+			})
+			if err == nil && policyResp.PolicyDocument != nil {
+				access.InlinePolicies = append(access.InlinePolicies, InlinePolicy{
+					PolicyName:     policyName,
+					PolicyDocument: decodeDocument(*policyResp.PolicyDocument),
+				})
+			}
+		}
+	}
+
+	// Get group policies
+	groupsPaginator := iam.NewListGroupsForUserPaginator(c.iam, &iam.ListGroupsForUserInput{
+		UserName: aws.String(userName),
+	})
+	for groupsPaginator.HasMorePages() {
+		page, err := groupsPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list groups for %s: %w", userName, err)
+		}
+		for _, group := range page.Groups {
+			access.Groups = append(access.Groups, c.getGroupAccess(ctx, aws.ToString(group.GroupName)))
+		}
+	}
+
+	// Get the documents of every attached managed policy
+	policies := append([]PolicyInfo(nil), access.AttachedPolicies...)
+	for _, group := range access.Groups {
+		policies = append(policies, group.AttachedPolicies...)
+	}
+	for _, policy := range policies {
+		if _, ok := access.Documents[policy.PolicyARN]; ok {
+			continue
+		}
+		if detail, err := c.GetPolicyDocument(ctx, policy.PolicyARN); err == nil {
+			access.Documents[policy.PolicyARN] = detail.PolicyDocument
+		}
+	}
+
+	return access, nil
+}
+
+// getGroupAccess returns a group's attached and inline policies. Policies
+// that cannot be read are left out.
+func (c *Client) getGroupAccess(ctx context.Context, groupName string) GroupAccess {
+	group := GroupAccess{GroupName: groupName}
+
+	attachedResp, err := c.iam.ListAttachedGroupPolicies(ctx, &iam.ListAttachedGroupPoliciesInput{
+		GroupName: aws.String(groupName),
+	})
+	if err == nil {
+		group.AttachedPolicies = attachedPolicyInfos(attachedResp.AttachedPolicies)
+	}
+
+	inlineResp, err := c.iam.ListGroupPolicies(ctx, &iam.ListGroupPoliciesInput{
+		GroupName: aws.String(groupName),
+	})
+	if err == nil {
+		for _, policyName := range inlineResp.PolicyNames {
+			policyResp, err := c.iam.GetGroupPolicy(ctx, &iam.GetGroupPolicyInput{
+				GroupName:  aws.String(groupName),
+				PolicyName: aws.String(policyName),
+			})
+			if err == nil && policyResp.PolicyDocument != nil {
+				group.InlinePolicies = append(group.InlinePolicies, InlinePolicy{
+					PolicyName:     policyName,
+					PolicyDocument: decodeDocument(*policyResp.PolicyDocument),
+				})
+			}
+		}
+	}
+
+	return group
+}
+
+func attachedPolicyInfos(attached []types.AttachedPolicy) []PolicyInfo {
+	policies := make([]PolicyInfo, 0, len(attached))
+	for _, policy := range attached {
+		policies = append(policies, PolicyInfo{
+			PolicyName: aws.ToString(policy.PolicyName),
+			PolicyARN:  aws.ToString(policy.PolicyArn),
+		})
+	}
+	return policies
+}
+
+// AccessKeyUser returns the name of the user that owns an access key
+func (c *Client) AccessKeyUser(ctx context.Context, accessKeyID string) (string, error) {
+	resp, err := c.iam.GetAccessKeyLastUsed(ctx, &iam.GetAccessKeyLastUsedInput{
+		AccessKeyId: aws.String(accessKeyID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up access key %s: %w", accessKeyID, err)
+	}
+	if resp.UserName == nil {
+		return "", fmt.Errorf("access key %s has no owning user", accessKeyID)
+	}
+	return *resp.UserName, nil
+}
+
 // GetCredentialReport generates and returns the credential report
 func (c *Client) GetCredentialReport(ctx context.Context) (*CredentialReport, error) {
 	// Generate credential report
@@ -630,10 +774,18 @@ func (c *Client) UpdateAccessKey(ctx context.Context, userName, accessKeyID, sta
 // ParsePolicyDocument parses a JSON policy document string
 func ParsePolicyDocument(document string) (*PolicyDocument, error) {
 	var doc PolicyDocument
-	if err := json.Unmarshal([]byte(document), &doc); err != nil {
+	if err := json.Unmarshal([]byte(document), &doc); err == nil {
+		return &doc, nil
+	}
+	// Statement may be a single object rather than a list
+	var single struct {
+		Version   string    `json:"Version"`
+		Statement Statement `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(document), &single); err != nil {
 		return nil, fmt.Errorf("failed to parse policy document: %w", err)
 	}
-	return &doc, nil
+	return &PolicyDocument{Version: single.Version, Statement: []Statement{single.Statement}}, nil
 }
 
 // Helper functions
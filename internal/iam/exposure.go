@@ -0,0 +1,342 @@
+package iam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// CLIRunner runs an aws CLI command and returns its output. Access Analyzer
+// is read through the CLI rather than an SDK client.
+type CLIRunner func(ctx context.Context, args []string) (string, error)
+
+// exposureAnalyzerName names the analyzer a remediation plan creates when
+// the account has none
+const exposureAnalyzerName = "clanker-external-access"
+
+const publicAccessBlockAll = "BlockPublicAcls=true,IgnorePublicAcls=true,BlockPublicPolicy=true,RestrictPublicBuckets=true"
+
+// AnalyzerFinding is an active Access Analyzer finding: a resource that a
+// principal outside the account, or anyone, can access
+type AnalyzerFinding struct {
+	ID           string            `json:"id"`
+	Resource     string            `json:"resource"`
+	ResourceType string            `json:"resourceType"`
+	IsPublic     bool              `json:"isPublic"`
+	Principal    map[string]string `json:"principal,omitempty"`
+	Action       []string          `json:"action,omitempty"`
+	Condition    map[string]string `json:"condition,omitempty"`
+	AnalyzerARN  string            `json:"analyzerArn,omitempty"`
+}
+
+// CrossAccountTrust is a role that a principal in another account can assume
+type CrossAccountTrust struct {
+	RoleName  string `json:"role_name"`
+	RoleARN   string `json:"role_arn"`
+	Principal string `json:"principal"`
+	// Conditioned is set when the trust statement has a condition, such as
+	// an external ID
+	Conditioned bool `json:"conditioned"`
+}
+
+// ExposureReport combines Access Analyzer findings with the role trusts
+// found in the account's trust policies
+type ExposureReport struct {
+	Analyzers []string            `json:"analyzers"`
+	Findings  []AnalyzerFinding   `json:"findings"`
+	Trusts    []CrossAccountTrust `json:"trusts"`
+	// AnalyzerError is why Access Analyzer could not be read; the trusts
+	// are still reported
+	AnalyzerError string `json:"analyzer_error,omitempty"`
+}
+
+// SetCLI sets the runner Access Analyzer operations use
+func (c *Client) SetCLI(run CLIRunner) {
+	c.cli = run
+}
+
+func (c *Client) runCLIJSON(ctx context.Context, out any, args ...string) error {
+	if c.cli == nil {
+		return fmt.Errorf("the aws CLI is not available to the IAM client")
+	}
+	if c.region != "" {
+		args = append(args, "--region", c.region)
+	}
+	raw, err := c.cli(ctx, append(args, "--output", "json"))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("failed to parse aws %s %s output: %w", args[0], args[1], err)
+	}
+	return nil
+}
+
+// ListAccessAnalyzerFindings returns the active findings of every active
+// external access analyzer, and the names of those analyzers
+func (c *Client) ListAccessAnalyzerFindings(ctx context.Context) ([]AnalyzerFinding, []string, error) {
+	var analyzers struct {
+		Analyzers []struct {
+			Arn    string `json:"arn"`
+			Name   string `json:"name"`
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"analyzers"`
+	}
+	if err := c.runCLIJSON(ctx, &analyzers, "accessanalyzer", "list-analyzers"); err != nil {
+		return nil, nil, err
+	}
+
+	var findings []AnalyzerFinding
+	var names []string
+	for _, a := range analyzers.Analyzers {
+		// Unused access analyzers report a different kind of finding
+		if a.Status != "ACTIVE" || (a.Type != "ACCOUNT" && a.Type != "ORGANIZATION") {
+			continue
+		}
+		names = append(names, a.Name)
+		var page struct {
+			Findings []AnalyzerFinding `json:"findings"`
+		}
+		if err := c.runCLIJSON(ctx, &page, "accessanalyzer", "list-findings", "--analyzer-arn", a.Arn,
+			"--filter", `{"status":{"eq":["ACTIVE"]}}`); err != nil {
+			return nil, names, err
+		}
+		for _, f := range page.Findings {
+			f.AnalyzerARN = a.Arn
+			findings = append(findings, f)
+		}
+	}
+	return findings, names, nil
+}
+
+// CrossAccountTrusts returns the role trusts that name a principal outside
+// the account
+func (c *Client) CrossAccountTrusts(ctx context.Context) ([]CrossAccountTrust, error) {
+	roles, err := c.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return crossAccountTrusts(roles, c.GetAccountID()), nil
+}
+
+func crossAccountTrusts(roles []RoleInfo, accountID string) []CrossAccountTrust {
+	var trusts []CrossAccountTrust
+	for _, r := range roles {
+		if r.AssumeRolePolicyDocument == "" {
+			continue
+		}
+
+		var trustPolicy TrustPolicy
+		if err := json.Unmarshal([]byte(r.AssumeRolePolicyDocument), &trustPolicy); err != nil {
+			continue
+		}
+
+		for _, stmt := range trustPolicy.Statement {
+			for _, p := range extractPrincipals(stmt.Principal) {
+				if strings.Contains(p, "arn:aws:iam::") && !strings.Contains(p, accountID) {
+					trusts = append(trusts, CrossAccountTrust{
+						RoleName:    r.RoleName,
+						RoleARN:     r.RoleARN,
+						Principal:   p,
+						Conditioned: stmt.Condition != nil,
+					})
+				}
+			}
+		}
+	}
+	return trusts
+}
+
+// GetExposureReport builds the external exposure report. An Access Analyzer
+// failure is recorded in the report rather than returned, so the role
+// trusts are still reported.
+func (c *Client) GetExposureReport(ctx context.Context) (*ExposureReport, error) {
+	trusts, err := c.CrossAccountTrusts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &ExposureReport{Trusts: trusts}
+	findings, analyzers, err := c.ListAccessAnalyzerFindings(ctx)
+	report.Findings = findings
+	report.Analyzers = analyzers
+	if err != nil {
+		report.AnalyzerError = err.Error()
+	}
+	return report, nil
+}
+
+// findingFor returns the Access Analyzer finding for resource, if any
+func (r *ExposureReport) findingFor(resource string) *AnalyzerFinding {
+	for i := range r.Findings {
+		if r.Findings[i].Resource == resource {
+			return &r.Findings[i]
+		}
+	}
+	return nil
+}
+
+// trusted reports whether the trust policies already list resource
+func (r *ExposureReport) trusted(resource string) bool {
+	for _, t := range r.Trusts {
+		if t.RoleARN == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// Format renders the report: public resources first, then resources
+// shared with other accounts, then role trusts marked by whether Access
+// Analyzer reports them too
+func (r *ExposureReport) Format() string {
+	var public, shared []AnalyzerFinding
+	for _, f := range r.Findings {
+		if f.IsPublic {
+			public = append(public, f)
+		} else if f.ResourceType != "AWS::IAM::Role" || !r.trusted(f.Resource) {
+			shared = append(shared, f)
+		}
+	}
+	sortFindings(public)
+	sortFindings(shared)
+
+	var sb strings.Builder
+	sb.WriteString("External Exposure Report\n")
+	sb.WriteString(strings.Repeat("=", 24) + "\n")
+	sb.WriteString(fmt.Sprintf("%d public resource(s), %d resource(s) shared with other accounts, %d cross-account role trust(s)\n",
+		len(public), len(shared), len(r.Trusts)))
+
+	switch {
+	case r.AnalyzerError != "":
+		sb.WriteString(fmt.Sprintf("\nAccess Analyzer unavailable: %s\nOnly role trust policies were checked.\n", r.AnalyzerError))
+	case len(r.Analyzers) == 0:
+		sb.WriteString("\nNo active external access analyzer in this region; only role trust policies were checked.\n")
+	default:
+		sb.WriteString(fmt.Sprintf("Analyzers: %s\n", strings.Join(r.Analyzers, ", ")))
+	}
+
+	if len(public) > 0 {
+		sb.WriteString("\nPublic Resources:\n")
+		for _, f := range public {
+			sb.WriteString(fmt.Sprintf("  [CRITICAL] %s %s%s\n", f.ResourceType, f.Resource, describeAccess(f)))
+		}
+	}
+	if len(shared) > 0 {
+		sb.WriteString("\nShared With Other Accounts:\n")
+		for _, f := range shared {
+			sb.WriteString(fmt.Sprintf("  [HIGH] %s %s%s\n", f.ResourceType, f.Resource, describeAccess(f)))
+		}
+	}
+	if len(r.Trusts) > 0 {
+		sb.WriteString("\nCross-Account Role Trusts:\n")
+		for _, t := range r.Trusts {
+			var flags []string
+			if !t.Conditioned {
+				flags = append(flags, "no condition")
+			}
+			if len(r.Analyzers) > 0 && r.AnalyzerError == "" {
+				if r.findingFor(t.RoleARN) != nil {
+					flags = append(flags, "confirmed by Access Analyzer")
+				} else {
+					flags = append(flags, "not an active Access Analyzer finding (archived or trusted zone)")
+				}
+			}
+			line := fmt.Sprintf("  %s trusts %s", t.RoleName, t.Principal)
+			if len(flags) > 0 {
+				line += " [" + strings.Join(flags, "; ") + "]"
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	if len(public) == 0 && len(shared) == 0 && len(r.Trusts) == 0 {
+		sb.WriteString("\nNo external access found\n")
+	}
+	return sb.String()
+}
+
+func sortFindings(findings []AnalyzerFinding) {
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].ResourceType != findings[j].ResourceType {
+			return findings[i].ResourceType < findings[j].ResourceType
+		}
+		return findings[i].Resource < findings[j].Resource
+	})
+}
+
+// describeAccess is who can do what in a finding
+func describeAccess(f AnalyzerFinding) string {
+	var principals []string
+	for _, v := range f.Principal {
+		principals = append(principals, v)
+	}
+	sort.Strings(principals)
+	out := ""
+	if len(principals) > 0 {
+		out += " to " + strings.Join(principals, ", ")
+	}
+	if len(f.Action) > 0 {
+		actions := f.Action
+		if len(actions) > 3 {
+			actions = append(append([]string(nil), actions[:3]...), fmt.Sprintf("+%d more", len(f.Action)-3))
+		}
+		out += " (" + strings.Join(actions, ", ") + ")"
+	}
+	return out
+}
+
+// RemediationPlan builds a maker plan for the exposure the AWS CLI can
+// close safely: creating an analyzer when there is none and blocking public
+// access to public buckets. Everything else needs a policy change someone
+// has to review, so it becomes a note. It returns nil when there is
+// nothing to run.
+func (r *ExposureReport) RemediationPlan(question string, now time.Time) *maker.Plan {
+	plan := &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "aws",
+		Question:  question,
+	}
+	if len(r.Analyzers) == 0 && r.AnalyzerError == "" {
+		plan.Commands = append(plan.Commands, maker.Command{
+			Args:   []string{"accessanalyzer", "create-analyzer", "--analyzer-name", exposureAnalyzerName, "--type", "ACCOUNT"},
+			Reason: "Turn on Access Analyzer so public and cross-account access is reported continuously",
+		})
+	}
+
+	findings := append([]AnalyzerFinding(nil), r.Findings...)
+	sortFindings(findings)
+	for _, f := range findings {
+		switch {
+		case f.IsPublic && f.ResourceType == "AWS::S3::Bucket":
+			bucket := strings.TrimPrefix(f.Resource, "arn:aws:s3:::")
+			plan.Commands = append(plan.Commands, maker.Command{
+				Args:   []string{"s3api", "put-public-access-block", "--bucket", bucket, "--public-access-block-configuration", publicAccessBlockAll},
+				Reason: "Block public access to " + bucket,
+			})
+		case f.ResourceType == "AWS::IAM::Role":
+			// Role trusts are covered below from the trust policies
+		default:
+			plan.Notes = append(plan.Notes, fmt.Sprintf("Review the resource policy of %s %s%s", f.ResourceType, f.Resource, describeAccess(f)))
+		}
+	}
+	for _, t := range r.Trusts {
+		if !t.Conditioned {
+			plan.Notes = append(plan.Notes, fmt.Sprintf("Role %s trusts %s without a condition: add sts:ExternalId or aws:PrincipalOrgID (clanker ask \"fix iam\" plans the trust policy change)", t.RoleName, t.Principal))
+		}
+	}
+	if len(r.Findings) > 0 {
+		plan.Notes = append(plan.Notes, "Archive findings for intended access with aws accessanalyzer update-findings --status ARCHIVED so they stop being reported")
+	}
+
+	if len(plan.Commands) == 0 {
+		return nil
+	}
+	plan.Summary = fmt.Sprintf("Close external exposure: %d remediation command(s)", len(plan.Commands))
+	return plan
+}
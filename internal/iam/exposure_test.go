@@ -0,0 +1,128 @@
+package iam
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListAccessAnalyzerFindings(t *testing.T) {
+	var calls [][]string
+	c := &Client{region: "eu-west-1", cli: func(ctx context.Context, args []string) (string, error) {
+		calls = append(calls, args)
+		switch args[1] {
+		case "list-analyzers":
+			return `{"analyzers": [
+				{"arn": "arn:aws:access-analyzer:eu-west-1:111111111111:analyzer/account", "name": "account", "type": "ACCOUNT", "status": "ACTIVE"},
+				{"arn": "arn:aws:access-analyzer:eu-west-1:111111111111:analyzer/unused", "name": "unused", "type": "ACCOUNT_UNUSED_ACCESS", "status": "ACTIVE"}
+			]}`, nil
+		case "list-findings":
+			return `{"findings": [{"id": "f-1", "resource": "arn:aws:s3:::assets", "resourceType": "AWS::S3::Bucket", "isPublic": true, "principal": {"AWS": "*"}, "action": ["s3:GetObject"]}]}`, nil
+		}
+		return "", errors.New("unexpected call")
+	}}
+
+	findings, analyzers, err := c.ListAccessAnalyzerFindings(context.Background())
+	if err != nil {
+		t.Fatalf("ListAccessAnalyzerFindings() error = %v", err)
+	}
+	if !reflect.DeepEqual(analyzers, []string{"account"}) || len(findings) != 1 {
+		t.Fatalf("analyzers = %v, findings = %+v", analyzers, findings)
+	}
+	if findings[0].AnalyzerARN != "arn:aws:access-analyzer:eu-west-1:111111111111:analyzer/account" {
+		t.Errorf("finding analyzer = %s", findings[0].AnalyzerARN)
+	}
+	want := []string{"accessanalyzer", "list-findings", "--analyzer-arn", "arn:aws:access-analyzer:eu-west-1:111111111111:analyzer/account",
+		"--filter", `{"status":{"eq":["ACTIVE"]}}`, "--region", "eu-west-1", "--output", "json"}
+	if len(calls) != 2 || !reflect.DeepEqual(calls[1], want) {
+		t.Errorf("calls = %v", calls)
+	}
+
+	if _, _, err := (&Client{}).ListAccessAnalyzerFindings(context.Background()); err == nil {
+		t.Error("a client without a CLI runner should fail")
+	}
+}
+
+func TestCrossAccountTrusts(t *testing.T) {
+	roles := []RoleInfo{
+		{RoleName: "vendor", RoleARN: "arn:aws:iam::111111111111:role/vendor",
+			AssumeRolePolicyDocument: `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::222222222222:root"}, "Action": "sts:AssumeRole"}]}`},
+		{RoleName: "audit", RoleARN: "arn:aws:iam::111111111111:role/audit",
+			AssumeRolePolicyDocument: `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": ["arn:aws:iam::333333333333:root"]}, "Action": "sts:AssumeRole", "Condition": {"StringEquals": {"sts:ExternalId": "x"}}}]}`},
+		{RoleName: "lambda", AssumeRolePolicyDocument: `{"Statement": [{"Effect": "Allow", "Principal": {"Service": "lambda.amazonaws.com"}}]}`},
+		{RoleName: "local", AssumeRolePolicyDocument: `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::111111111111:root"}}]}`},
+	}
+	got := crossAccountTrusts(roles, "111111111111")
+	want := []CrossAccountTrust{
+		{RoleName: "vendor", RoleARN: "arn:aws:iam::111111111111:role/vendor", Principal: "arn:aws:iam::222222222222:root"},
+		{RoleName: "audit", RoleARN: "arn:aws:iam::111111111111:role/audit", Principal: "arn:aws:iam::333333333333:root", Conditioned: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("crossAccountTrusts() = %+v, want %+v", got, want)
+	}
+}
+
+func testExposureReport() *ExposureReport {
+	return &ExposureReport{
+		Analyzers: []string{"account"},
+		Findings: []AnalyzerFinding{
+			{ID: "f-1", Resource: "arn:aws:s3:::assets", ResourceType: "AWS::S3::Bucket", IsPublic: true, Principal: map[string]string{"AWS": "*"}, Action: []string{"s3:GetObject"}},
+			{ID: "f-2", Resource: "arn:aws:kms:eu-west-1:111111111111:key/abc", ResourceType: "AWS::KMS::Key", Principal: map[string]string{"AWS": "444444444444"}, Action: []string{"kms:Decrypt"}},
+			{ID: "f-3", Resource: "arn:aws:iam::111111111111:role/vendor", ResourceType: "AWS::IAM::Role", Principal: map[string]string{"AWS": "222222222222"}},
+		},
+		Trusts: []CrossAccountTrust{
+			{RoleName: "vendor", RoleARN: "arn:aws:iam::111111111111:role/vendor", Principal: "arn:aws:iam::222222222222:root"},
+			{RoleName: "audit", RoleARN: "arn:aws:iam::111111111111:role/audit", Principal: "arn:aws:iam::333333333333:root", Conditioned: true},
+		},
+	}
+}
+
+func TestExposureReportFormat(t *testing.T) {
+	out := testExposureReport().Format()
+	for _, want := range []string{
+		"1 public resource(s), 1 resource(s) shared with other accounts, 2 cross-account role trust(s)",
+		"  [CRITICAL] AWS::S3::Bucket arn:aws:s3:::assets to * (s3:GetObject)",
+		"  [HIGH] AWS::KMS::Key arn:aws:kms:eu-west-1:111111111111:key/abc to 444444444444 (kms:Decrypt)",
+		"  vendor trusts arn:aws:iam::222222222222:root [no condition; confirmed by Access Analyzer]",
+		"  audit trusts arn:aws:iam::333333333333:root [not an active Access Analyzer finding (archived or trusted zone)]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Format() missing %q:\n%s", want, out)
+		}
+	}
+
+	failed := &ExposureReport{AnalyzerError: "AccessDeniedException"}
+	if out := failed.Format(); !strings.Contains(out, "Access Analyzer unavailable: AccessDeniedException") || !strings.Contains(out, "No external access found") {
+		t.Errorf("Format() = %s", out)
+	}
+}
+
+func TestExposureRemediationPlan(t *testing.T) {
+	plan := testExposureReport().RemediationPlan("external exposure report", testNow)
+	if plan == nil || len(plan.Commands) != 1 {
+		t.Fatalf("plan = %+v, want one command", plan)
+	}
+	want := []string{"s3api", "put-public-access-block", "--bucket", "assets", "--public-access-block-configuration", publicAccessBlockAll}
+	if !reflect.DeepEqual(plan.Commands[0].Args, want) {
+		t.Errorf("args = %v, want %v", plan.Commands[0].Args, want)
+	}
+	notes := strings.Join(plan.Notes, "\n")
+	for _, want := range []string{"Review the resource policy of AWS::KMS::Key", "Role vendor trusts arn:aws:iam::222222222222:root without a condition"} {
+		if !strings.Contains(notes, want) {
+			t.Errorf("notes missing %q:\n%s", want, notes)
+		}
+	}
+	if strings.Contains(notes, "Role audit") {
+		t.Errorf("conditioned trust should not get a note:\n%s", notes)
+	}
+
+	noAnalyzer := (&ExposureReport{}).RemediationPlan("q", testNow)
+	if noAnalyzer == nil || noAnalyzer.Commands[0].Args[1] != "create-analyzer" {
+		t.Errorf("plan without an analyzer = %+v", noAnalyzer)
+	}
+	if plan := (&ExposureReport{Analyzers: []string{"account"}}).RemediationPlan("q", testNow); plan != nil {
+		t.Errorf("clean report plan = %+v, want nil", plan)
+	}
+}
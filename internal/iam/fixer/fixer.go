@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("iam.fixer")
+
 // SubAgent provides fix capabilities for IAM security findings
 type SubAgent struct {
 	client IAMClient
@@ -22,9 +26,7 @@ func NewSubAgent(client IAMClient, debug bool) *SubAgent {
 
 // GenerateFixPlan generates a remediation plan for a security finding
 func (f *SubAgent) GenerateFixPlan(ctx context.Context, finding SecurityFinding) (*FixPlan, error) {
-	if f.debug {
-		fmt.Printf("[iam-fixer] Generating fix plan for finding: %s (%s)\n", finding.Type, finding.ResourceARN)
-	}
+	logger.Debugf("Generating fix plan for finding: %s (%s)", finding.Type, finding.ResourceARN)
 
 	plan := &FixPlan{
 		ID:        generatePlanID(),
@@ -143,24 +145,17 @@ func (f *SubAgent) ApplyPlan(ctx context.Context, plan *FixPlan, confirm bool) e
 		return fmt.Errorf("plan execution requires confirmation")
 	}
 
-	if f.debug {
-		fmt.Printf("[iam-fixer] Applying plan: %s\n", plan.Summary)
-	}
+	logger.Debugf("Applying plan: %s", plan.Summary)
 
 	for i, cmd := range plan.Commands {
-		if f.debug {
-			fmt.Printf("[iam-fixer] Executing command %d/%d: %s on %s\n",
-				i+1, len(plan.Commands), cmd.Action, cmd.ResourceARN)
-		}
+		logger.Debugf("Executing command %d/%d: %s on %s", i+1, len(plan.Commands), cmd.Action, cmd.ResourceARN)
 
 		if err := f.executeCommand(ctx, cmd); err != nil {
 			return fmt.Errorf("command %d (%s) failed: %w", i+1, cmd.Action, err)
 		}
 	}
 
-	if f.debug {
-		fmt.Println("[iam-fixer] Plan applied successfully")
-	}
+	logger.Debug("Plan applied successfully")
 
 	return nil
 }
@@ -222,8 +222,11 @@ func (c *Client) executeIAMOperation(ctx context.Context, op IAMOperation) (stri
 		if userName == "" {
 			return "", fmt.Errorf("user_name required")
 		}
-		// This would need additional implementation
-		return fmt.Sprintf("User policies for %s (implementation pending)", userName), nil
+		access, err := c.GetUserAccess(ctx, userName)
+		if err != nil {
+			return "", err
+		}
+		return formatUserAccess(access), nil
 
 	// ACCESS KEYS
 	case "list_access_keys":
@@ -294,6 +297,20 @@ func (c *Client) executeIAMOperation(ctx context.Context, op IAMOperation) (stri
 	case "find_cross_account_trusts":
 		return c.findCrossAccountTrusts(ctx)
 
+	case "list_access_analyzer_findings":
+		findings, analyzers, err := c.ListAccessAnalyzerFindings(ctx)
+		if err != nil {
+			return "", err
+		}
+		return (&ExposureReport{Analyzers: analyzers, Findings: findings}).Format(), nil
+
+	case "get_external_exposure_report":
+		report, err := c.GetExposureReport(ctx)
+		if err != nil {
+			return "", err
+		}
+		return report.Format(), nil
+
 	case "analyze_permissions":
 		if roleName != "" {
 			detail, err := c.GetRoleDetails(ctx, roleName)
@@ -309,7 +326,14 @@ func (c *Client) executeIAMOperation(ctx context.Context, op IAMOperation) (stri
 			}
 			return fmt.Sprintf("Policy: %s\nDocument:\n%s", policyARN, detail.PolicyDocument), nil
 		}
-		return "", fmt.Errorf("role_name or policy_arn required")
+		if userName != "" {
+			access, err := c.GetUserAccess(ctx, userName)
+			if err != nil {
+				return "", err
+			}
+			return formatUserAccess(access), nil
+		}
+		return "", fmt.Errorf("role_name, policy_arn or user_name required")
 
 	default:
 		return "", fmt.Errorf("unknown operation: %s", op.Operation)
@@ -776,42 +800,19 @@ func (c *Client) findUnusedRoles(ctx context.Context) (string, error) {
 }
 
 func (c *Client) findCrossAccountTrusts(ctx context.Context) (string, error) {
-	roles, err := c.ListRoles(ctx)
+	trusts, err := c.CrossAccountTrusts(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	var crossAccountRoles []string
-	myAccountID := c.GetAccountID()
-
-	for _, r := range roles {
-		if r.AssumeRolePolicyDocument == "" {
-			continue
-		}
-
-		var trustPolicy TrustPolicy
-		if err := json.Unmarshal([]byte(r.AssumeRolePolicyDocument), &trustPolicy); err != nil {
-			continue
-		}
-
-		for _, stmt := range trustPolicy.Statement {
-			principals := extractPrincipals(stmt.Principal)
-			for _, p := range principals {
-				if strings.Contains(p, "arn:aws:iam::") && !strings.Contains(p, myAccountID) {
-					crossAccountRoles = append(crossAccountRoles, fmt.Sprintf("%s trusts %s", r.RoleName, p))
-				}
-			}
-		}
-	}
-
-	if len(crossAccountRoles) == 0 {
+	if len(trusts) == 0 {
 		return "No cross-account trust relationships found", nil
 	}
 
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("Found %d cross-account trust relationships:\n\n", len(crossAccountRoles)))
-	for _, r := range crossAccountRoles {
-		sb.WriteString(fmt.Sprintf("- %s\n", r))
+	sb.WriteString(fmt.Sprintf("Found %d cross-account trust relationships:\n\n", len(trusts)))
+	for _, t := range trusts {
+		sb.WriteString(fmt.Sprintf("- %s trusts %s\n", t.RoleName, t.Principal))
 	}
 	return sb.String(), nil
 }
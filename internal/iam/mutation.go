@@ -0,0 +1,618 @@
+package iam
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// MutationOp is an IAM change a question asks for
+type MutationOp int
+
+const (
+	// MutationNone means the question asks for no change
+	MutationNone MutationOp = iota
+	// MutationCreateRole creates a role trusted by a service or account
+	MutationCreateRole
+	// MutationAttachPolicy attaches a managed policy to a role
+	MutationAttachPolicy
+	// MutationDetachPolicy detaches a managed policy from a role
+	MutationDetachPolicy
+	// MutationDeactivateUser deactivates a user's access keys and denies
+	// the user everything
+	MutationDeactivateUser
+	// MutationDeactivateKey deactivates a single access key
+	MutationDeactivateKey
+	// MutationRotateKey creates a new access key and deactivates the old one
+	MutationRotateKey
+	// MutationRollbackPolicy makes an earlier policy version the default
+	MutationRollbackPolicy
+)
+
+// denyAllPolicyARN is the AWS managed policy that denies every action. It
+// locks a user out without deleting the password, keys or group memberships
+// a later reactivation needs.
+const denyAllPolicyARN = "arn:aws:iam::aws:policy/AWSDenyAll"
+
+// Mutation is a question translated into an IAM change
+type Mutation struct {
+	Op     MutationOp
+	Role   string
+	User   string
+	Policy string
+	// VersionID is the policy version a rollback restores; empty means the
+	// version before the current default
+	VersionID   string
+	AccessKeyID string
+	// Principal is who may assume a created role: a service principal such
+	// as lambda.amazonaws.com or an account root ARN
+	Principal string
+}
+
+var (
+	createRoleRe = regexp.MustCompile(`(?i)\bcreate\s+(?:an?\s+)?(?:new\s+)?(?:iam\s+)?role\b`)
+	attachRe     = regexp.MustCompile(`(?i)\battach\b`)
+	detachRe     = regexp.MustCompile(`(?i)\b(?:detach|remove)\b`)
+	deactivateRe = regexp.MustCompile(`(?i)\b(?:deactivate|disable|lock|suspend)\b`)
+	rotateRe     = regexp.MustCompile(`(?i)\brotate\b`)
+	rollbackRe   = regexp.MustCompile(`(?i)\b(?:roll\s*back|revert)\b`)
+	policyWordRe = regexp.MustCompile(`(?i)\bpolic(?:y|ies)\b|arn:aws[a-z-]*:iam::[0-9a-z]+:policy/`)
+	keyWordRe    = regexp.MustCompile(`(?i)\b(?:access\s+)?keys?\b`)
+	userWordRe   = regexp.MustCompile(`(?i)\buser\b`)
+
+	policyARNRe = regexp.MustCompile(`arn:aws[a-z-]*:iam::(?:aws|\d{12}):policy/[\w+=,.@/-]+`)
+	accessKeyRe = regexp.MustCompile(`\b(?:AKIA|ASIA)[A-Z0-9]{16}\b`)
+	versionRe   = regexp.MustCompile(`(?i)\bversion\s+v?(\d+)\b|\bv(\d+)\b`)
+	accountRe   = regexp.MustCompile(`\b(\d{12})\b`)
+
+	roleNameRe   = regexp.MustCompile(`(?i)\brole\s+(?:named\s+|called\s+)?["'` + "`" + `]?([\w+=,.@-]+)`)
+	namedRe      = regexp.MustCompile(`(?i)\b(?:named|called)\s+["'` + "`" + `]?([\w+=,.@-]+)`)
+	userNameRe   = regexp.MustCompile(`(?i)\buser\s+(?:named\s+|called\s+)?["'` + "`" + `]?([\w+=,.@-]+)`)
+	policyNameRe = regexp.MustCompile(`(?i)\b(?:attach|detach|remove|policy|back|revert)\s+(?:the\s+)?(?:managed\s+|aws\s+|customer\s+)?(?:policy\s+)?(?:named\s+|called\s+)?["'` + "`" + `]?([\w+=,.@-]+)`)
+)
+
+// notNames are words the name patterns can land on that never name a role,
+// user or policy
+var notNames = map[string]bool{
+	"a": true, "an": true, "the": true, "my": true, "our": true, "new": true, "this": true, "that": true,
+	"to": true, "from": true, "for": true, "on": true, "of": true, "with": true, "and": true, "in": true,
+	"iam": true, "role": true, "roles": true, "user": true, "users": true, "policy": true, "policies": true,
+	"named": true, "called": true, "managed": true, "aws": true, "customer": true, "version": true,
+	"access": true, "key": true, "keys": true, "it": true, "its": true, "that's": true, "which": true,
+	"trusted": true, "assumable": true, "previous": true, "last": true, "default": true,
+}
+
+// servicePrincipals maps the words a question uses for a service to the
+// principal its roles trust
+var servicePrincipals = []struct {
+	re        *regexp.Regexp
+	principal string
+}{
+	{regexp.MustCompile(`(?i)\becs[- ]tasks?\b|\bfargate\b`), "ecs-tasks.amazonaws.com"},
+	{regexp.MustCompile(`(?i)\blambda\b`), "lambda.amazonaws.com"},
+	{regexp.MustCompile(`(?i)\bec2\b`), "ec2.amazonaws.com"},
+	{regexp.MustCompile(`(?i)\beks\b`), "eks.amazonaws.com"},
+	{regexp.MustCompile(`(?i)\bstep functions\b|\bstates\b`), "states.amazonaws.com"},
+	{regexp.MustCompile(`(?i)\bglue\b`), "glue.amazonaws.com"},
+	{regexp.MustCompile(`(?i)\bcodebuild\b`), "codebuild.amazonaws.com"},
+	{regexp.MustCompile(`(?i)\bsagemaker\b`), "sagemaker.amazonaws.com"},
+	{regexp.MustCompile(`(?i)\bapi gateway\b|\bapigateway\b`), "apigateway.amazonaws.com"},
+}
+
+// ParseMutation reads an IAM change from question. Questions that ask for
+// no change return MutationNone.
+func ParseMutation(question string) Mutation {
+	m := Mutation{}
+	hasPolicy := policyWordRe.MatchString(question)
+	keyID := accessKeyRe.FindString(question)
+
+	switch {
+	case createRoleRe.MatchString(question):
+		m.Op = MutationCreateRole
+	case rollbackRe.MatchString(question) && hasPolicy:
+		m.Op = MutationRollbackPolicy
+	case rotateRe.MatchString(question) && (keyID != "" || keyWordRe.MatchString(question)):
+		m.Op = MutationRotateKey
+	case deactivateRe.MatchString(question) && keyID != "":
+		m.Op = MutationDeactivateKey
+	case deactivateRe.MatchString(question) && userWordRe.MatchString(question):
+		m.Op = MutationDeactivateUser
+	case detachRe.MatchString(question) && hasPolicy:
+		m.Op = MutationDetachPolicy
+	case attachRe.MatchString(question) && hasPolicy:
+		m.Op = MutationAttachPolicy
+	default:
+		return m
+	}
+
+	m.AccessKeyID = keyID
+	switch m.Op {
+	case MutationCreateRole:
+		m.Role = firstName(question, roleNameRe, namedRe)
+		m.Principal = parsePrincipal(question)
+		if strings.Contains(strings.ToLower(question), "polic") {
+			m.Policy = parsePolicy(question)
+		}
+	case MutationAttachPolicy, MutationDetachPolicy:
+		m.Role = firstName(question, roleNameRe)
+		m.Policy = parsePolicy(question)
+	case MutationRollbackPolicy:
+		m.Policy = parsePolicy(question)
+		if v := versionRe.FindStringSubmatch(question); v != nil {
+			m.VersionID = "v" + v[1] + v[2]
+		}
+	case MutationDeactivateUser, MutationDeactivateKey, MutationRotateKey:
+		m.User = firstName(question, userNameRe)
+	}
+	return m
+}
+
+// firstName returns the first match of the patterns that could be a name
+func firstName(question string, patterns ...*regexp.Regexp) string {
+	for _, re := range patterns {
+		for _, match := range re.FindAllStringSubmatch(question, -1) {
+			name := strings.TrimRight(match[1], ".,")
+			if name != "" && !notNames[strings.ToLower(name)] && !accessKeyRe.MatchString(name) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// parsePolicy returns the policy ARN in question, or the policy name after
+// the verb or the word policy
+func parsePolicy(question string) string {
+	if arn := policyARNRe.FindString(question); arn != "" {
+		return arn
+	}
+	rest := roleNameRe.ReplaceAllString(question, " ")
+	return firstName(rest, policyNameRe)
+}
+
+// parsePrincipal returns who a created role should trust: an account when
+// one is named, otherwise the first service mentioned
+func parsePrincipal(question string) string {
+	if m := accountRe.FindStringSubmatch(question); m != nil {
+		return "arn:aws:iam::" + m[1] + ":root"
+	}
+	for _, s := range servicePrincipals {
+		if s.re.MatchString(question) {
+			return s.principal
+		}
+	}
+	return ""
+}
+
+// MutationState is what the account holds that a mutation plan depends on
+type MutationState struct {
+	// Role is the role the mutation names; nil when it does not exist
+	Role *RoleDetail
+	// Policies are the account's customer managed policies, used to resolve
+	// policy names
+	Policies []PolicyInfo
+	// Policy is the policy a rollback applies to
+	Policy *PolicyDetail
+	// Keys are the access keys of the user the mutation names
+	Keys []AccessKeyInfo
+}
+
+// PlanMutation builds the maker plan for m from the account's current state.
+// The plan is returned for review; nothing is changed.
+func (a *Agent) PlanMutation(ctx context.Context, m Mutation, question string, now time.Time) (*maker.Plan, error) {
+	var state MutationState
+	if m.Role != "" {
+		if role, err := a.client.GetRoleDetails(ctx, m.Role); err == nil {
+			state.Role = role
+		} else if m.Op != MutationCreateRole {
+			return nil, err
+		}
+	}
+	if m.Policy != "" && !strings.HasPrefix(m.Policy, "arn:") {
+		policies, err := a.client.ListPolicies(ctx)
+		if err != nil {
+			return nil, err
+		}
+		state.Policies = policies
+	}
+	if m.Op == MutationRollbackPolicy && m.Policy != "" {
+		policy, err := a.client.GetPolicyDocument(ctx, resolvePolicyARN(m.Policy, state))
+		if err != nil {
+			return nil, err
+		}
+		state.Policy = policy
+	}
+	if m.User == "" && m.AccessKeyID != "" {
+		user, err := a.client.AccessKeyUser(ctx, m.AccessKeyID)
+		if err != nil {
+			return nil, err
+		}
+		m.User = user
+	}
+	if m.User != "" && m.Op != MutationDeactivateKey {
+		keys, err := a.client.ListAccessKeys(ctx, m.User)
+		if err != nil {
+			return nil, err
+		}
+		state.Keys = keys
+	}
+	return BuildMutationPlan(m, state, question, now)
+}
+
+// BuildMutationPlan builds the plan for m against state
+func BuildMutationPlan(m Mutation, state MutationState, question string, now time.Time) (*maker.Plan, error) {
+	plan := &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "aws",
+		Question:  question,
+	}
+	var err error
+	switch m.Op {
+	case MutationCreateRole:
+		err = createRolePlan(plan, m, state)
+	case MutationAttachPolicy:
+		err = attachPolicyPlan(plan, m, state)
+	case MutationDetachPolicy:
+		err = detachPolicyPlan(plan, m, state)
+	case MutationDeactivateUser:
+		err = deactivateUserPlan(plan, m, state)
+	case MutationDeactivateKey:
+		err = deactivateKeyPlan(plan, m)
+	case MutationRotateKey:
+		err = rotateKeyPlan(plan, m, state)
+	case MutationRollbackPolicy:
+		err = rollbackPolicyPlan(plan, m, state)
+	default:
+		return nil, fmt.Errorf("the question asks for no IAM change")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// resolvePolicyARN returns the ARN of a policy given by ARN or name.
+// Customer managed policies win over AWS managed ones of the same name.
+func resolvePolicyARN(policy string, state MutationState) string {
+	if strings.HasPrefix(policy, "arn:") {
+		return policy
+	}
+	for _, p := range state.Policies {
+		if strings.EqualFold(p.PolicyName, policy) {
+			return p.PolicyARN
+		}
+	}
+	return "arn:aws:iam::aws:policy/" + policy
+}
+
+// trustPolicy is the assume-role policy document trusting principal
+func trustPolicy(principal string) string {
+	key := "Service"
+	if strings.HasPrefix(principal, "arn:") {
+		key = "AWS"
+	}
+	// Statement would render an empty Resource, which trust policies reject
+	doc := map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []map[string]any{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{key: principal},
+			"Action":    "sts:AssumeRole",
+		}},
+	}
+	data, _ := json.Marshal(doc)
+	return string(data)
+}
+
+func createRolePlan(plan *maker.Plan, m Mutation, state MutationState) error {
+	if m.Role == "" {
+		return fmt.Errorf("name the role to create (e.g. \"create role api-exec for lambda\")")
+	}
+	if state.Role != nil {
+		return fmt.Errorf("role %s already exists (%s)", m.Role, state.Role.RoleARN)
+	}
+	if m.Principal == "" {
+		return fmt.Errorf("say which service or account may assume %s (e.g. \"for lambda\" or \"for account 123456789012\")", m.Role)
+	}
+	plan.Summary = fmt.Sprintf("Create IAM role %s trusted by %s", m.Role, m.Principal)
+	plan.Commands = []maker.Command{
+		{Args: []string{"iam", "create-role", "--role-name", m.Role, "--assume-role-policy-document", trustPolicy(m.Principal)},
+			Reason: "Create " + m.Role + " assumable by " + m.Principal},
+	}
+	if m.Policy != "" {
+		policyARN := resolvePolicyARN(m.Policy, state)
+		plan.Summary += " with " + policyName(policyARN)
+		plan.Commands = append(plan.Commands, maker.Command{
+			Args:   []string{"iam", "attach-role-policy", "--role-name", m.Role, "--policy-arn", policyARN},
+			Reason: "Grant " + m.Role + " the permissions in " + policyName(policyARN),
+		})
+	}
+	if strings.HasPrefix(m.Principal, "arn:") {
+		plan.Notes = append(plan.Notes, "Cross-account trust: add an sts:ExternalId condition if a third party assumes this role")
+	}
+	return nil
+}
+
+func attachPolicyPlan(plan *maker.Plan, m Mutation, state MutationState) error {
+	if m.Role == "" || m.Policy == "" {
+		return fmt.Errorf("name the policy and the role to attach it to (e.g. \"attach ReadOnlyAccess to role auditor\")")
+	}
+	if state.Role == nil {
+		return fmt.Errorf("no IAM role named %s", m.Role)
+	}
+	policyARN := resolvePolicyARN(m.Policy, state)
+	for _, p := range state.Role.AttachedPolicies {
+		if p.PolicyARN == policyARN {
+			return fmt.Errorf("%s is already attached to role %s", policyName(policyARN), m.Role)
+		}
+	}
+	plan.Summary = fmt.Sprintf("Attach %s to IAM role %s", policyName(policyARN), m.Role)
+	plan.Commands = []maker.Command{
+		{Args: []string{"iam", "attach-role-policy", "--role-name", m.Role, "--policy-arn", policyARN},
+			Reason: "Grant " + m.Role + " the permissions in " + policyName(policyARN)},
+	}
+	plan.Notes = append(plan.Notes, "Undo with: aws iam detach-role-policy --role-name "+m.Role+" --policy-arn "+policyARN)
+	return nil
+}
+
+func detachPolicyPlan(plan *maker.Plan, m Mutation, state MutationState) error {
+	if m.Role == "" || m.Policy == "" {
+		return fmt.Errorf("name the policy and the role to detach it from (e.g. \"detach AdministratorAccess from role deployer\")")
+	}
+	if state.Role == nil {
+		return fmt.Errorf("no IAM role named %s", m.Role)
+	}
+	var attached []string
+	for _, p := range state.Role.AttachedPolicies {
+		if p.PolicyARN == m.Policy || strings.EqualFold(p.PolicyName, m.Policy) {
+			plan.Summary = fmt.Sprintf("Detach %s from IAM role %s", p.PolicyName, m.Role)
+			plan.Commands = []maker.Command{
+				{Args: []string{"iam", "detach-role-policy", "--role-name", m.Role, "--policy-arn", p.PolicyARN},
+					Reason: "Remove the permissions in " + p.PolicyName + " from " + m.Role},
+			}
+			plan.Notes = append(plan.Notes, "Undo with: aws iam attach-role-policy --role-name "+m.Role+" --policy-arn "+p.PolicyARN)
+			return nil
+		}
+		attached = append(attached, p.PolicyName)
+	}
+	if len(attached) == 0 {
+		return fmt.Errorf("role %s has no managed policies attached", m.Role)
+	}
+	return fmt.Errorf("%s is not attached to role %s (attached: %s)", m.Policy, m.Role, strings.Join(attached, ", "))
+}
+
+func deactivateUserPlan(plan *maker.Plan, m Mutation, state MutationState) error {
+	if m.User == "" {
+		return fmt.Errorf("name the user to deactivate (e.g. \"deactivate user ci-legacy\")")
+	}
+	plan.Summary = fmt.Sprintf("Deactivate IAM user %s", m.User)
+	for _, key := range state.Keys {
+		if key.Status != "Active" {
+			continue
+		}
+		plan.Commands = append(plan.Commands, deactivateKeyCommand(m.User, key.AccessKeyId, "Stop API access with "+describeKey(key)))
+	}
+	plan.Commands = append(plan.Commands, maker.Command{
+		Args:   []string{"iam", "attach-user-policy", "--user-name", m.User, "--policy-arn", denyAllPolicyARN},
+		Reason: "Deny every action, including console sign-in, while keeping the user's credentials for a later reactivation",
+	})
+	plan.Notes = append(plan.Notes,
+		"Reactivate by detaching AWSDenyAll and setting the keys back to Active",
+		"The console password is kept; delete the login profile separately (apply with --destroyer) to remove it",
+	)
+	return nil
+}
+
+func deactivateKeyPlan(plan *maker.Plan, m Mutation) error {
+	if m.User == "" {
+		return fmt.Errorf("could not find the user that owns access key %s", m.AccessKeyID)
+	}
+	plan.Summary = fmt.Sprintf("Deactivate access key %s of IAM user %s", m.AccessKeyID, m.User)
+	plan.Commands = []maker.Command{deactivateKeyCommand(m.User, m.AccessKeyID, "Stop API access with "+m.AccessKeyID)}
+	plan.Notes = append(plan.Notes, "The key is deactivated, not deleted; set it back to Active if something still needs it")
+	return nil
+}
+
+func rotateKeyPlan(plan *maker.Plan, m Mutation, state MutationState) error {
+	if m.User == "" {
+		return fmt.Errorf("name the user whose access key to rotate (e.g. \"rotate access keys for user ci-deploy\")")
+	}
+	var old []AccessKeyInfo
+	for _, key := range state.Keys {
+		if m.AccessKeyID == "" && key.Status == "Active" || key.AccessKeyId == m.AccessKeyID {
+			old = append(old, key)
+		}
+	}
+	if m.AccessKeyID != "" && len(old) == 0 {
+		return fmt.Errorf("user %s has no access key %s", m.User, m.AccessKeyID)
+	}
+	// IAM allows two keys per user, so a user with two has no room for
+	// the new one
+	if len(state.Keys) >= 2 {
+		var ids []string
+		for _, key := range state.Keys {
+			ids = append(ids, key.AccessKeyId+" ("+key.Status+")")
+		}
+		return fmt.Errorf("user %s already has two access keys (%s); delete one before rotating", m.User, strings.Join(ids, ", "))
+	}
+
+	plan.Summary = fmt.Sprintf("Rotate the access key of IAM user %s", m.User)
+	plan.Commands = []maker.Command{
+		{Args: []string{"iam", "create-access-key", "--user-name", m.User},
+			Reason: "Create the replacement key"},
+	}
+	for _, key := range old {
+		plan.Commands = append(plan.Commands, deactivateKeyCommand(m.User, key.AccessKeyId, "Retire "+describeKey(key)))
+	}
+	plan.Notes = append(plan.Notes,
+		"The new secret access key is printed once by create-access-key; store it before the output scrolls away",
+		"Old keys are deactivated, not deleted; reactivate one if a consumer still uses it, and delete it once nothing fails",
+	)
+	return nil
+}
+
+func rollbackPolicyPlan(plan *maker.Plan, m Mutation, state MutationState) error {
+	if m.Policy == "" || state.Policy == nil {
+		return fmt.Errorf("name the customer managed policy to roll back (e.g. \"roll back policy deploy-access to v2\")")
+	}
+	policy := state.Policy
+	versions := append([]PolicyVersion(nil), policy.Versions...)
+	sort.Slice(versions, func(i, j int) bool { return versions[i].CreateDate.After(versions[j].CreateDate) })
+
+	var current *PolicyVersion
+	for i := range versions {
+		if versions[i].IsDefaultVersion {
+			current = &versions[i]
+		}
+	}
+	if current == nil {
+		return fmt.Errorf("policy %s has no default version", policy.PolicyName)
+	}
+
+	target := ""
+	if m.VersionID != "" {
+		for _, v := range versions {
+			if v.VersionId == m.VersionID {
+				target = v.VersionId
+			}
+		}
+		if target == "" {
+			return fmt.Errorf("policy %s has no version %s (versions: %s)", policy.PolicyName, m.VersionID, versionIDs(versions))
+		}
+		if target == current.VersionId {
+			return fmt.Errorf("%s is already the default version of policy %s", target, policy.PolicyName)
+		}
+	} else {
+		for _, v := range versions {
+			if !v.IsDefaultVersion && v.CreateDate.Before(current.CreateDate) {
+				target = v.VersionId
+				break
+			}
+		}
+		if target == "" {
+			return fmt.Errorf("policy %s has no version older than the default %s", policy.PolicyName, current.VersionId)
+		}
+	}
+
+	plan.Summary = fmt.Sprintf("Roll back policy %s from %s to %s", policy.PolicyName, current.VersionId, target)
+	plan.Commands = []maker.Command{
+		{Args: []string{"iam", "set-default-policy-version", "--policy-arn", policy.PolicyARN, "--version-id", target},
+			Reason: "Make " + target + " the version in effect"},
+	}
+	plan.Notes = append(plan.Notes, "Undo with: aws iam set-default-policy-version --policy-arn "+policy.PolicyARN+" --version-id "+current.VersionId)
+	return nil
+}
+
+func deactivateKeyCommand(user, keyID, reason string) maker.Command {
+	return maker.Command{
+		Args:   []string{"iam", "update-access-key", "--user-name", user, "--access-key-id", keyID, "--status", "Inactive"},
+		Reason: reason,
+	}
+}
+
+// describeKey names a key with when it was last used
+func describeKey(key AccessKeyInfo) string {
+	if key.LastUsedDate == nil {
+		return key.AccessKeyId + " (never used)"
+	}
+	return fmt.Sprintf("%s (last used %s)", key.AccessKeyId, key.LastUsedDate.UTC().Format("2006-01-02"))
+}
+
+// policyName is the last path element of a policy ARN
+func policyName(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+func versionIDs(versions []PolicyVersion) string {
+	ids := make([]string, 0, len(versions))
+	for _, v := range versions {
+		ids = append(ids, v.VersionId)
+	}
+	return strings.Join(ids, ", ")
+}
+
+// MakerPlan converts a fix plan into a maker plan so it can be stored,
+// reviewed and applied with ask --apply. It returns nil when the fix plan
+// has no commands, only guidance.
+func (p *FixPlan) MakerPlan(question string, now time.Time) (*maker.Plan, error) {
+	if p == nil || len(p.Commands) == 0 {
+		return nil, nil
+	}
+	plan := &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "aws",
+		Question:  question,
+		Summary:   p.Summary,
+		Notes:     append(append([]string(nil), p.Warnings...), p.Notes...),
+	}
+	for _, c := range p.Commands {
+		args, err := fixCommandArgs(c)
+		if err != nil {
+			return nil, err
+		}
+		plan.Commands = append(plan.Commands, maker.Command{Args: args, Reason: c.Reason})
+	}
+	return plan, nil
+}
+
+// fixCommandArgs is the AWS CLI form of a fix command
+func fixCommandArgs(c FixCommand) ([]string, error) {
+	param := func(name string) (string, error) {
+		v, _ := c.Parameters[name].(string)
+		if v == "" {
+			return "", fmt.Errorf("%s parameter required for %s", name, c.Action)
+		}
+		return v, nil
+	}
+	// Role and user ARNs end in the name, as extractRoleName expects
+	name := extractRoleName(c.ResourceARN)
+
+	switch c.Action {
+	case ActionCreatePolicyVersion:
+		doc, err := param("document")
+		if err != nil {
+			return nil, err
+		}
+		return []string{"iam", "create-policy-version", "--policy-arn", c.ResourceARN, "--policy-document", doc, "--set-as-default"}, nil
+	case ActionUpdateTrustPolicy:
+		doc, err := param("document")
+		if err != nil {
+			return nil, err
+		}
+		return []string{"iam", "update-assume-role-policy", "--role-name", name, "--policy-document", doc}, nil
+	case ActionAttachPolicy, ActionDetachPolicy:
+		policyARN, err := param("policy_arn")
+		if err != nil {
+			return nil, err
+		}
+		op := "attach-role-policy"
+		if c.Action == ActionDetachPolicy {
+			op = "detach-role-policy"
+		}
+		return []string{"iam", op, "--role-name", name, "--policy-arn", policyARN}, nil
+	case ActionDeactivateAccessKey, ActionDeleteAccessKey:
+		keyID, err := param("access_key_id")
+		if err != nil {
+			return nil, err
+		}
+		if c.Action == ActionDeleteAccessKey {
+			return []string{"iam", "delete-access-key", "--user-name", name, "--access-key-id", keyID}, nil
+		}
+		return deactivateKeyCommand(name, keyID, "").Args, nil
+	case ActionDeletePolicyVersion:
+		versionID, err := param("version_id")
+		if err != nil {
+			return nil, err
+		}
+		return []string{"iam", "delete-policy-version", "--policy-arn", c.ResourceARN, "--version-id", versionID}, nil
+	}
+	return nil, fmt.Errorf("unsupported fix action: %s", c.Action)
+}
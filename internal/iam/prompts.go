@@ -31,7 +31,7 @@ POLICIES:
 USERS:
 - list_users: List all IAM users
 - get_user_details: Get detailed user information (requires user_name parameter)
-- list_user_policies: List attached and inline policies for a user (requires user_name parameter)
+- list_user_policies: List a user's attached, inline and group-inherited policies with an effective-permissions summary (requires user_name parameter)
 
 ACCESS KEYS:
 - list_access_keys: List access keys for a user (requires user_name parameter)
@@ -50,7 +50,9 @@ SECURITY ANALYSIS:
 - check_mfa_status: Check MFA status for all users
 - find_unused_roles: Find roles that have not been used recently
 - find_cross_account_trusts: Find roles with cross-account trust relationships
-- analyze_permissions: Analyze permissions granted by a specific policy or role
+- list_access_analyzer_findings: List active IAM Access Analyzer findings for public and cross-account resources
+- get_external_exposure_report: Combine Access Analyzer findings with cross-account role trusts into one external exposure report
+- analyze_permissions: Analyze permissions granted by a specific policy, role or user
 
 Respond with ONLY a JSON object in this format:
 {
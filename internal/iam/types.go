@@ -1,6 +1,10 @@
 package iam
 
-import "time"
+import (
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
 
 // IAMOperation represents an IAM operation requested by the LLM
 type IAMOperation struct {
@@ -103,7 +107,9 @@ type Response struct {
 	Content  string            `json:"content,omitempty"`
 	Plan     *FixPlan          `json:"plan,omitempty"`
 	Findings []SecurityFinding `json:"findings,omitempty"`
-	Error    error             `json:"error,omitempty"`
+	// Remediation is a maker plan for findings the AWS CLI can fix
+	Remediation *maker.Plan `json:"remediation,omitempty"`
+	Error       error       `json:"error,omitempty"`
 }
 
 // RoleInfo contains basic role information
@@ -174,6 +180,7 @@ type Statement struct {
 	Effect    string      `json:"Effect"`
 	Principal interface{} `json:"Principal,omitempty"`
 	Action    interface{} `json:"Action"`
+	NotAction interface{} `json:"NotAction,omitempty"`
 	Resource  interface{} `json:"Resource"`
 	Condition interface{} `json:"Condition,omitempty"`
 }
@@ -227,6 +234,27 @@ type CredentialReportEntry struct {
 	AccessKey2LastUsedService string     `json:"access_key_2_last_used_service,omitempty"`
 }
 
+// UserAccess contains every policy that grants a user permissions: its own
+// attached and inline policies and those of its groups
+type UserAccess struct {
+	UserName            string         `json:"user_name"`
+	UserARN             string         `json:"user_arn"`
+	PermissionsBoundary string         `json:"permissions_boundary,omitempty"`
+	AttachedPolicies    []PolicyInfo   `json:"attached_policies"`
+	InlinePolicies      []InlinePolicy `json:"inline_policies"`
+	Groups              []GroupAccess  `json:"groups,omitempty"`
+	// Documents are the default version documents of the attached managed
+	// policies, by policy ARN
+	Documents map[string]string `json:"documents,omitempty"`
+}
+
+// GroupAccess contains the policies a user inherits from one group
+type GroupAccess struct {
+	GroupName        string         `json:"group_name"`
+	AttachedPolicies []PolicyInfo   `json:"attached_policies"`
+	InlinePolicies   []InlinePolicy `json:"inline_policies"`
+}
+
 // GroupInfo contains basic group information
 type GroupInfo struct {
 	GroupName  string    `json:"group_name"`
@@ -0,0 +1,175 @@
+package incident
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+)
+
+// maxDiagnosisIssues is how many SRE issues the evidence lists before
+// counting the rest
+const maxDiagnosisIssues = 10
+
+// Format renders the evidence and the hypothesis as markdown
+func (b *Bundle) Format() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Incident: %s\n\n", b.Title)
+	fmt.Fprintf(&sb, "ID %s, evidence from %s to %s", b.ID, b.Since.Format("15:04"), b.StartedAt.Format("15:04 MST 2006-01-02"))
+	if b.Namespace != "" {
+		fmt.Fprintf(&sb, ", namespace %s", b.Namespace)
+	}
+	sb.WriteString(".\n")
+
+	if b.Hypothesis != "" {
+		fmt.Fprintf(&sb, "\n## Hypothesis\n\n%s\n", b.Hypothesis)
+	}
+	sb.WriteString(b.evidence())
+
+	if len(b.Skipped) > 0 {
+		fmt.Fprintf(&sb, "\nNot configured: %s\n", strings.Join(b.Skipped, ", "))
+	}
+	if len(b.Failures) > 0 {
+		sb.WriteString("\nCould not read:\n")
+		names := make([]string, 0, len(b.Failures))
+		for name := range b.Failures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&sb, "- %s: %s\n", name, b.Failures[name])
+		}
+	}
+	return sb.String()
+}
+
+// evidence renders one section per source
+func (b *Bundle) evidence() string {
+	var sb strings.Builder
+
+	sb.WriteString("\n## Kubernetes\n\n")
+	switch d := b.Diagnosis; {
+	case d == nil:
+		sb.WriteString("No diagnosis.\n")
+	case len(d.Issues) == 0 && len(d.RootCauses) == 0:
+		fmt.Fprintf(&sb, "%s\n", orNone(d.Summary, "No issues found."))
+	default:
+		fmt.Fprintf(&sb, "%s\n\n", d.Summary)
+		for _, rc := range d.RootCauses {
+			fmt.Fprintf(&sb, "- Root cause (%s confidence) %s/%s: %s\n", rc.Confidence, rc.Namespace, rc.Pod, rc.Summary)
+		}
+		for i, issue := range d.Issues {
+			if i == maxDiagnosisIssues {
+				fmt.Fprintf(&sb, "- ...and %d more issues\n", len(d.Issues)-maxDiagnosisIssues)
+				break
+			}
+			fmt.Fprintf(&sb, "- %s %s/%s: %s\n", strings.ToUpper(string(issue.Severity)), issue.ResourceType, issue.ResourceName, issue.Message)
+		}
+	}
+
+	sb.WriteString("\n## Deploys\n\n")
+	if len(b.Deploys) == 0 {
+		sb.WriteString("No deploys in the window.\n")
+	} else {
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "    WHEN\tSOURCE\tNAME\tVERSION\tENV\tSTATUS")
+		for _, d := range b.Deploys {
+			fmt.Fprintf(tw, "    %s\t%s\t%s\t%s\t%s\t%s\n", clock(d.At), d.Source, d.Name, d.Version, orNone(d.Environment, "-"), orNone(d.Status, "-"))
+		}
+		tw.Flush()
+	}
+
+	sb.WriteString("\n## Edge (Cloudflare)\n\n")
+	if len(b.Edge) == 0 {
+		sb.WriteString("No unusual traffic.\n")
+	}
+	for _, e := range b.Edge {
+		fmt.Fprintf(&sb, "- %s: %s (%d requests in the window)\n", e.Zone, e.Reason, e.Requests)
+	}
+
+	sb.WriteString("\n## Errors (Sentry)\n\n")
+	if len(b.Errors) == 0 {
+		sb.WriteString("No unresolved issues seen in the window.\n")
+	}
+	for _, e := range b.Errors {
+		label := ""
+		if e.New {
+			label = " NEW"
+		}
+		fmt.Fprintf(&sb, "- %s%s: %s (%s events, %d users, last seen %s)\n", e.ID, label, e.Title, e.Events, e.Users, clock(e.LastSeen))
+	}
+
+	sb.WriteString("\n## Load balancers (ALB 5xx)\n\n")
+	if len(b.LoadBalancers) == 0 {
+		sb.WriteString("No 5xx in the window.\n")
+	}
+	for _, l := range b.LoadBalancers {
+		fmt.Fprintf(&sb, "- %s: %d ELB 5xx, %d target 5xx of %d requests (%.1f%%)\n", l.Name, l.ELB5xx, l.Target5xx, l.Requests, l.Rate()*100)
+	}
+	return sb.String()
+}
+
+// Prompt asks the AI for the hypothesis that best explains the evidence
+func (b *Bundle) Prompt() string {
+	return fmt.Sprintf(`You are the incident commander for an ongoing incident: %q.
+The evidence below was gathered from Kubernetes, deploy history, Cloudflare, Sentry and AWS load balancers between %s and %s.
+
+%s
+
+Correlate the evidence by time and by service. Answer with:
+1. The most likely cause, naming the evidence that supports it (a deploy, a crashing workload, an error spike, a 5xx source).
+2. Other plausible causes the evidence does not rule out.
+3. The first two or three things to check or do next.
+Say plainly when the evidence is thin or contradictory. Do not invent evidence that is not listed.`,
+		b.Title, b.Since.Format(time.RFC3339), b.StartedAt.Format(time.RFC3339), strings.TrimSpace(b.evidence()))
+}
+
+// Dir returns ~/.clanker/incidents
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "incidents"), nil
+}
+
+// Write saves the bundle under dir/<id> as incident.json, holding all the
+// evidence, and incident.md, the rendered summary. It returns the bundle's
+// directory.
+func (b *Bundle) Write(dir string) (string, error) {
+	path := filepath.Join(dir, secfile.SafeSlug(b.ID))
+	if err := secfile.EnsurePrivateDir(path); err != nil {
+		return "", fmt.Errorf("failed to create incident directory: %w", err)
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal incident: %w", err)
+	}
+	if err := secfile.WritePrivate(filepath.Join(path, "incident.json"), data); err != nil {
+		return "", fmt.Errorf("failed to write incident: %w", err)
+	}
+	if err := secfile.WritePrivate(filepath.Join(path, "incident.md"), []byte(b.Format())); err != nil {
+		return "", fmt.Errorf("failed to write incident summary: %w", err)
+	}
+	return path, nil
+}
+
+func clock(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format("15:04")
+}
+
+func orNone(s, none string) string {
+	if strings.TrimSpace(s) == "" {
+		return none
+	}
+	return s
+}
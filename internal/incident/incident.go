@@ -0,0 +1,348 @@
+// Package incident gathers the evidence for an outage from every source
+// clanker can reach, in parallel: the Kubernetes diagnosis of the affected
+// namespace, recent Helm and GitHub deploys, Cloudflare edge anomalies,
+// Sentry error spikes and ALB 5xx counts. The evidence, and the AI's
+// hypothesis of what connects it, is kept as a bundle on disk so the
+// incident can be reviewed after the fact.
+package incident
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/bgdnvk/clanker/internal/sentry"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultWindow is how far back evidence is gathered when no window is given
+const DefaultWindow = 2 * time.Hour
+
+// sourceTimeout bounds each source so a slow one cannot hold up the bundle
+const sourceTimeout = 90 * time.Second
+
+// Runner runs a CLI command and returns its output. The Cloudflare runner
+// takes an HTTP method, an API path and a body instead.
+type Runner func(ctx context.Context, args []string) (string, error)
+
+// SentryAPI is the part of sentry.Client the Sentry source uses
+type SentryAPI interface {
+	ListIssues(ctx context.Context, orgSlug string, opts sentry.IssueListOptions) ([]sentry.Issue, string, error)
+}
+
+// Sources are how the evidence is reached. A nil source is left out of the
+// bundle and listed as not configured.
+type Sources struct {
+	// Kubectl runs kubectl commands; it finds the namespace of the service
+	Kubectl Runner
+	// Diagnose runs the SRE diagnosis of a namespace
+	Diagnose func(ctx context.Context, namespace string) (*sre.DiagnosticReport, error)
+	// Helm runs helm commands
+	Helm Runner
+	// GitHub runs gh commands against GitHubRepo (owner/name)
+	GitHub     Runner
+	GitHubRepo string
+	// Cloudflare calls the Cloudflare API; Zones limits it to those zones
+	Cloudflare Runner
+	Zones      []string
+	// Sentry lists the issues of SentryOrg
+	Sentry    SentryAPI
+	SentryOrg string
+	// AWS runs aws CLI commands
+	AWS Runner
+	// Ask sends a prompt to the AI and returns its answer
+	Ask func(ctx context.Context, prompt string) (string, error)
+}
+
+// Source names, as they appear in the bundle
+const (
+	SourceKubernetes = "kubernetes"
+	SourceHelm       = "helm"
+	SourceGitHub     = "github"
+	SourceCloudflare = "cloudflare"
+	SourceSentry     = "sentry"
+	SourceALB        = "alb"
+	SourceAI         = "ai"
+)
+
+// Bundle is everything gathered for one incident
+type Bundle struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Service   string    `json:"service,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	// Since is the start of the window evidence was gathered over
+	Since time.Time `json:"since"`
+
+	Diagnosis *sre.DiagnosticReport `json:"diagnosis,omitempty"`
+	// Deploys are Helm releases and GitHub deployments in the window,
+	// newest first
+	Deploys []Deploy `json:"deploys,omitempty"`
+	// Edge are the Cloudflare zones whose traffic looks unusual
+	Edge []EdgeAnomaly `json:"edge,omitempty"`
+	// Errors are the Sentry issues seen in the window, most frequent first
+	Errors []ErrorSpike `json:"errors,omitempty"`
+	// LoadBalancers are the ALBs that returned 5xx in the window
+	LoadBalancers []LoadBalancer5xx `json:"loadBalancers,omitempty"`
+
+	Hypothesis string `json:"hypothesis,omitempty"`
+	// Skipped lists sources that are not configured
+	Skipped []string `json:"skipped,omitempty"`
+	// Failures holds the sources that could not be read, with the reason
+	Failures map[string]string `json:"failures,omitempty"`
+}
+
+// Deploy is one release of the service
+type Deploy struct {
+	Source string    `json:"source"` // helm or github
+	Name   string    `json:"name"`
+	At     time.Time `json:"at"`
+	// Version is the Helm revision and chart, or the git ref and SHA
+	Version     string `json:"version,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Actor       string `json:"actor,omitempty"`
+	Status      string `json:"status,omitempty"`
+}
+
+// EdgeAnomaly is a Cloudflare zone whose traffic in the window differs from
+// the day before it
+type EdgeAnomaly struct {
+	Zone     string  `json:"zone"`
+	Requests int64   `json:"requests"`
+	Rate5xx  float64 `json:"rate5xx"`
+	// Baseline figures are hourly averages over the 24h before the window
+	BaselineRequests float64 `json:"baselineRequests"`
+	BaselineRate5xx  float64 `json:"baselineRate5xx"`
+	Reason           string  `json:"reason"`
+}
+
+// ErrorSpike is a Sentry issue seen in the window
+type ErrorSpike struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Project  string    `json:"project,omitempty"`
+	Events   string    `json:"events"`
+	Users    int       `json:"users"`
+	New      bool      `json:"new"` // first seen inside the window
+	LastSeen time.Time `json:"lastSeen"`
+	Link     string    `json:"link,omitempty"`
+}
+
+// LoadBalancer5xx is an ALB's request and 5xx counts over the window
+type LoadBalancer5xx struct {
+	Name      string `json:"name"`
+	Requests  int64  `json:"requests"`
+	ELB5xx    int64  `json:"elb5xx"`
+	Target5xx int64  `json:"target5xx"`
+}
+
+// Rate is the share of requests that ended in a 5xx
+func (l LoadBalancer5xx) Rate() float64 {
+	if l.Requests == 0 {
+		return 0
+	}
+	return float64(l.ELB5xx+l.Target5xx) / float64(l.Requests)
+}
+
+// Options describe the incident to gather evidence for
+type Options struct {
+	Title string
+	// Namespace overrides the namespace found from the title
+	Namespace string
+	Window    time.Duration
+	Now       time.Time
+}
+
+// Agent gathers incident evidence
+type Agent struct {
+	sources Sources
+	debug   bool
+}
+
+// NewAgent creates an incident agent that reads each source it is given
+func NewAgent(sources Sources, debug bool) *Agent {
+	return &Agent{sources: sources, debug: debug}
+}
+
+// Start gathers the evidence for opts in parallel and asks the AI for a
+// hypothesis that connects it. A source that fails is recorded in the
+// bundle rather than failing the others.
+func (a *Agent) Start(ctx context.Context, opts Options) *Bundle {
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	service := ServiceName(opts.Title)
+	b := &Bundle{
+		ID:        newID(opts.Now, service),
+		Title:     opts.Title,
+		Service:   service,
+		Namespace: opts.Namespace,
+		StartedAt: opts.Now.UTC(),
+		Since:     opts.Now.Add(-opts.Window).UTC(),
+		Failures:  map[string]string{},
+	}
+	if b.Namespace == "" && a.sources.Kubectl != nil {
+		b.Namespace = a.findNamespace(ctx, service)
+	}
+
+	var (
+		mu sync.Mutex
+		g  errgroup.Group
+	)
+	collect := func(name string, configured bool, run func(ctx context.Context) error) {
+		if !configured {
+			b.Skipped = append(b.Skipped, name)
+			return
+		}
+		g.Go(func() error {
+			ctx, cancel := context.WithTimeout(ctx, sourceTimeout)
+			defer cancel()
+			started := time.Now()
+			err := run(ctx)
+			if a.debug {
+				fmt.Printf("[incident] %s gathered in %s (err=%v)\n", name, time.Since(started).Round(time.Millisecond), err)
+			}
+			if err != nil {
+				mu.Lock()
+				b.Failures[name] = err.Error()
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+
+	var helmDeploys, githubDeploys []Deploy
+	collect(SourceKubernetes, a.sources.Diagnose != nil, func(ctx context.Context) error {
+		report, err := a.sources.Diagnose(ctx, b.Namespace)
+		b.Diagnosis = report
+		return err
+	})
+	collect(SourceHelm, a.sources.Helm != nil, func(ctx context.Context) (err error) {
+		helmDeploys, err = a.helmDeploys(ctx, b.Namespace, b.Since)
+		return err
+	})
+	collect(SourceGitHub, a.sources.GitHub != nil && a.sources.GitHubRepo != "", func(ctx context.Context) (err error) {
+		githubDeploys, err = a.githubDeploys(ctx, b.Since)
+		return err
+	})
+	collect(SourceCloudflare, a.sources.Cloudflare != nil, func(ctx context.Context) (err error) {
+		b.Edge, err = a.edgeAnomalies(ctx, b.Since, opts.Now)
+		return err
+	})
+	collect(SourceSentry, a.sources.Sentry != nil && a.sources.SentryOrg != "", func(ctx context.Context) (err error) {
+		b.Errors, err = a.errorSpikes(ctx, b.Since, opts.Now)
+		return err
+	})
+	collect(SourceALB, a.sources.AWS != nil, func(ctx context.Context) (err error) {
+		b.LoadBalancers, err = a.loadBalancer5xx(ctx, service, b.Since, opts.Now)
+		return err
+	})
+	_ = g.Wait()
+
+	b.Deploys = append(helmDeploys, githubDeploys...)
+	sort.SliceStable(b.Deploys, func(i, j int) bool { return b.Deploys[i].At.After(b.Deploys[j].At) })
+	sort.Strings(b.Skipped)
+
+	if a.sources.Ask != nil {
+		hypothesis, err := a.sources.Ask(ctx, b.Prompt())
+		if err != nil {
+			b.Failures[SourceAI] = err.Error()
+		} else {
+			b.Hypothesis = strings.TrimSpace(hypothesis)
+		}
+	} else {
+		b.Skipped = append(b.Skipped, SourceAI)
+	}
+	return b
+}
+
+// stopWords are left out when picking the service out of an incident title
+var stopWords = map[string]bool{
+	"the": true, "a": true, "an": true, "is": true, "are": true, "was": true, "our": true, "my": true,
+	"down": true, "up": true, "broken": true, "failing": true, "slow": true, "degraded": true, "outage": true,
+	"errors": true, "error": true, "returning": true, "timing": true, "out": true, "not": true, "working": true,
+	"service": true, "site": true, "api": true, "app": true, "in": true, "on": true, "for": true, "of": true,
+	"500s": true, "502s": true, "503s": true, "5xx": true, "high": true, "latency": true, "and": true,
+}
+
+// ServiceName picks the service an incident title is about: the first word
+// that is not a stop word, e.g. "checkout" from "checkout is down"
+func ServiceName(title string) string {
+	for _, w := range strings.Fields(strings.ToLower(title)) {
+		w = strings.Trim(w, `"'.,:;!?()`)
+		if w == "" || stopWords[w] {
+			continue
+		}
+		return w
+	}
+	return ""
+}
+
+// findNamespace returns the namespace named after service, or else the
+// namespace of a deployment named after it
+func (a *Agent) findNamespace(ctx context.Context, service string) string {
+	if service == "" {
+		return ""
+	}
+	var namespaces struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := runJSON(ctx, a.sources.Kubectl, &namespaces, "get", "namespaces", "-o", "json"); err == nil {
+		for _, ns := range namespaces.Items {
+			if ns.Metadata.Name == service {
+				return service
+			}
+		}
+		for _, ns := range namespaces.Items {
+			if strings.Contains(ns.Metadata.Name, service) {
+				return ns.Metadata.Name
+			}
+		}
+	}
+	var deployments struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := runJSON(ctx, a.sources.Kubectl, &deployments, "get", "deployments", "-A", "-o", "json"); err == nil {
+		for _, d := range deployments.Items {
+			if strings.Contains(d.Metadata.Name, service) {
+				return d.Metadata.Namespace
+			}
+		}
+	}
+	return ""
+}
+
+// runJSON runs a command that prints JSON and decodes the output into out
+func runJSON(ctx context.Context, run Runner, out any, args ...string) error {
+	raw, err := run(ctx, args)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("failed to parse %s output: %w", strings.Join(args[:min(2, len(args))], " "), err)
+	}
+	return nil
+}
+
+func newID(now time.Time, service string) string {
+	id := now.UTC().Format("20060102-150405")
+	if service != "" {
+		id += "-" + service
+	}
+	return id
+}
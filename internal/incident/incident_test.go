@@ -0,0 +1,201 @@
+package incident
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/bgdnvk/clanker/internal/sentry"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+func TestServiceName(t *testing.T) {
+	for title, want := range map[string]string{
+		"checkout is down":                   "checkout",
+		"The payments API is returning 502s": "payments",
+		"high latency on search":             "search",
+		"down":                               "",
+	} {
+		if got := ServiceName(title); got != want {
+			t.Errorf("ServiceName(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+type fakeSentry struct {
+	opts sentry.IssueListOptions
+}
+
+func (f *fakeSentry) ListIssues(ctx context.Context, orgSlug string, opts sentry.IssueListOptions) ([]sentry.Issue, string, error) {
+	f.opts = opts
+	return []sentry.Issue{
+		{ID: "1", ShortID: "CHECKOUT-9", Title: "PaymentGatewayTimeout", Count: "812", UserCount: 95,
+			FirstSeen: testNow.Add(-40 * time.Minute), LastSeen: testNow.Add(-time.Minute), Project: &sentry.Project{Slug: "checkout"}},
+		{ID: "2", ShortID: "CHECKOUT-3", Title: "KeyError: 'cart'", Count: "40", UserCount: 3,
+			FirstSeen: testNow.Add(-72 * time.Hour), LastSeen: testNow.Add(-30 * time.Minute)},
+	}, "", nil
+}
+
+func testSources(prompt *string) (Sources, *fakeSentry) {
+	fs := &fakeSentry{}
+	return Sources{
+		Kubectl: func(ctx context.Context, args []string) (string, error) {
+			switch strings.Join(args[:2], " ") {
+			case "get namespaces":
+				return `{"items":[{"metadata":{"name":"default"}},{"metadata":{"name":"shop-checkout"}}]}`, nil
+			}
+			return "", fmt.Errorf("unexpected kubectl %v", args)
+		},
+		Diagnose: func(ctx context.Context, namespace string) (*sre.DiagnosticReport, error) {
+			return &sre.DiagnosticReport{Scope: "namespace", Namespace: namespace, Summary: "1 critical issue",
+				Issues: []sre.Issue{{Severity: sre.SeverityCritical, ResourceType: "pod", ResourceName: "checkout-7f9c", Message: "CrashLoopBackOff"}}}, nil
+		},
+		Helm: func(ctx context.Context, args []string) (string, error) {
+			return `[{"name":"checkout","namespace":"shop-checkout","revision":"42","updated":"2026-10-15 11:20:03.123456 +0000 UTC","status":"deployed","chart":"checkout-1.8.0","app_version":"2.4.1"},
+				{"name":"redis","namespace":"shop-checkout","revision":"3","updated":"2026-09-01 08:00:00.0 +0000 UTC","status":"deployed","chart":"redis-18.0.0"}]`, nil
+		},
+		GitHub: func(ctx context.Context, args []string) (string, error) {
+			if args[0] == "api" {
+				return `[{"sha":"abcdef1234567","ref":"main","environment":"production","created_at":"2026-10-15T11:15:00Z","creator":{"login":"jane"}},
+					{"sha":"0000000","ref":"main","environment":"production","created_at":"2026-10-14T09:00:00Z","creator":{"login":"bob"}}]`, nil
+			}
+			return `[{"workflowName":"Deploy production","headBranch":"main","headSha":"abcdef1234567","status":"completed","conclusion":"success","createdAt":"2026-10-15T11:10:00Z"},
+				{"workflowName":"CI","headBranch":"main","headSha":"abcdef1234567","status":"completed","conclusion":"success","createdAt":"2026-10-15T11:05:00Z"}]`, nil
+		},
+		GitHubRepo: "acme/shop",
+		Cloudflare: func(ctx context.Context, args []string) (string, error) {
+			if args[1] == "/zones?per_page=50" {
+				return `{"result":[{"id":"z1","name":"shop.example.com"}]}`, nil
+			}
+			var groups []string
+			for h := 24; h > 0; h-- {
+				groups = append(groups, fmt.Sprintf(`{"dimensions":{"datetime":%q},"sum":{"requests":1000,"responseStatusMap":[{"edgeResponseStatus":200,"requests":995},{"edgeResponseStatus":502,"requests":5}]}}`,
+					testNow.Add(-time.Duration(h+2)*time.Hour).Format(time.RFC3339)))
+			}
+			for h := 2; h > 0; h-- {
+				groups = append(groups, fmt.Sprintf(`{"dimensions":{"datetime":%q},"sum":{"requests":900,"responseStatusMap":[{"edgeResponseStatus":200,"requests":720},{"edgeResponseStatus":502,"requests":180}]}}`,
+					testNow.Add(-time.Duration(h)*time.Hour).Format(time.RFC3339)))
+			}
+			return `{"data":{"viewer":{"zones":[{"httpRequests1hGroups":[` + strings.Join(groups, ",") + `]}]}}}`, nil
+		},
+		Sentry:    fs,
+		SentryOrg: "acme",
+		AWS: func(ctx context.Context, args []string) (string, error) {
+			if args[0] == "elbv2" {
+				return `[{"name":"checkout-alb","arn":"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/checkout-alb/50dc6c495c0c9188"},
+					{"name":"admin-alb","arn":"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/admin-alb/1111"}]`, nil
+			}
+			metric := args[5]
+			if args[7] != "Name=LoadBalancer,Value=app/checkout-alb/50dc6c495c0c9188" {
+				return "", fmt.Errorf("unexpected dimension %s", args[7])
+			}
+			sums := map[string]int{"RequestCount": 20000, "HTTPCode_ELB_5XX_Count": 300, "HTTPCode_Target_5XX_Count": 1700}
+			return fmt.Sprintf(`{"Datapoints":[{"Sum":%d}]}`, sums[metric]), nil
+		},
+		Ask: func(ctx context.Context, p string) (string, error) {
+			*prompt = p
+			return "The 11:20 checkout deploy (revision 42) is the likely cause.", nil
+		},
+	}, fs
+}
+
+func TestStart(t *testing.T) {
+	var prompt string
+	sources, fs := testSources(&prompt)
+	b := NewAgent(sources, false).Start(context.Background(), Options{Title: "checkout is down", Now: testNow})
+
+	if b.ID != "20261015-120000-checkout" || b.Namespace != "shop-checkout" || !b.Since.Equal(testNow.Add(-2*time.Hour)) {
+		t.Errorf("id, namespace, since = %s, %s, %s", b.ID, b.Namespace, b.Since)
+	}
+	if len(b.Failures) != 0 || len(b.Skipped) != 0 {
+		t.Fatalf("failures = %v, skipped = %v", b.Failures, b.Skipped)
+	}
+	if b.Diagnosis == nil || b.Diagnosis.Namespace != "shop-checkout" {
+		t.Errorf("diagnosis = %+v", b.Diagnosis)
+	}
+
+	if len(b.Deploys) != 3 {
+		t.Fatalf("deploys = %+v", b.Deploys)
+	}
+	if d := b.Deploys[0]; d.Source != SourceHelm || d.Name != "checkout" || d.Version != "revision 42, checkout-1.8.0 (app 2.4.1)" {
+		t.Errorf("newest deploy = %+v", d)
+	}
+	if d := b.Deploys[1]; d.Source != SourceGitHub || d.Version != "main@abcdef1" || d.Actor != "jane" {
+		t.Errorf("github deployment = %+v", d)
+	}
+	if d := b.Deploys[2]; d.Name != "Deploy production" || d.Status != "success" {
+		t.Errorf("workflow deploy = %+v", d)
+	}
+
+	if len(b.Edge) != 1 || !strings.Contains(b.Edge[0].Reason, "5xx rate 20.0% vs 0.5%") {
+		t.Errorf("edge = %+v", b.Edge)
+	}
+
+	if fs.opts.Query != "is:unresolved lastSeen:-120m" || fs.opts.Sort != "freq" {
+		t.Errorf("sentry search = %+v", fs.opts)
+	}
+	if len(b.Errors) != 2 || !b.Errors[0].New || b.Errors[1].New {
+		t.Errorf("errors = %+v", b.Errors)
+	}
+
+	if len(b.LoadBalancers) != 1 || b.LoadBalancers[0].Name != "checkout-alb" || b.LoadBalancers[0].Rate() != 0.1 {
+		t.Errorf("load balancers = %+v", b.LoadBalancers)
+	}
+
+	for _, s := range []string{`"checkout is down"`, "CrashLoopBackOff", "revision 42", "CHECKOUT-9 NEW", "checkout-alb: 300 ELB 5xx"} {
+		if !strings.Contains(prompt, s) {
+			t.Errorf("prompt missing %q:\n%s", s, prompt)
+		}
+	}
+	if b.Hypothesis == "" || !strings.Contains(b.Format(), "## Hypothesis") {
+		t.Errorf("hypothesis missing:\n%s", b.Format())
+	}
+}
+
+func TestStartRecordsFailuresAndSkips(t *testing.T) {
+	sources := Sources{
+		Diagnose: func(ctx context.Context, namespace string) (*sre.DiagnosticReport, error) {
+			return nil, fmt.Errorf("cluster unreachable")
+		},
+	}
+	b := NewAgent(sources, false).Start(context.Background(), Options{Title: "checkout is down", Namespace: "prod", Window: time.Hour, Now: testNow})
+	if b.Namespace != "prod" || b.Failures[SourceKubernetes] != "cluster unreachable" {
+		t.Errorf("namespace = %q, failures = %v", b.Namespace, b.Failures)
+	}
+	want := []string{SourceALB, SourceCloudflare, SourceGitHub, SourceHelm, SourceSentry, SourceAI}
+	if strings.Join(b.Skipped, ",") != strings.Join(want, ",") {
+		t.Errorf("skipped = %v, want %v", b.Skipped, want)
+	}
+	out := b.Format()
+	for _, s := range []string{"Not configured: alb, cloudflare", "- kubernetes: cluster unreachable"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("output missing %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	b := &Bundle{ID: "20261015-120000-checkout", Title: "checkout is down", StartedAt: testNow, Since: testNow.Add(-DefaultWindow)}
+	path, err := b.Write(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(path, "incident.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Bundle
+	if err := json.Unmarshal(data, &got); err != nil || got.Title != b.Title {
+		t.Errorf("incident.json = %s (%v)", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(path, "incident.md")); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("incident.md: %v, %v", info, err)
+	}
+}
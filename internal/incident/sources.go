@@ -0,0 +1,398 @@
+package incident
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/sentry"
+)
+
+const (
+	// maxZones and maxLoadBalancers bound the per-resource calls of the
+	// Cloudflare and ALB sources
+	maxZones         = 10
+	maxLoadBalancers = 10
+	// maxErrors is how many Sentry issues the bundle keeps
+	maxErrors = 10
+)
+
+// helmDeploys returns the Helm releases in namespace, or in every namespace
+// when it is empty, that changed since since or are not deployed
+func (a *Agent) helmDeploys(ctx context.Context, namespace string, since time.Time) ([]Deploy, error) {
+	args := []string{"list", "-o", "json"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	} else {
+		args = append(args, "-A")
+	}
+	var releases []struct {
+		Name       string `json:"name"`
+		Namespace  string `json:"namespace"`
+		Revision   string `json:"revision"`
+		Updated    string `json:"updated"`
+		Status     string `json:"status"`
+		Chart      string `json:"chart"`
+		AppVersion string `json:"app_version"`
+	}
+	if err := runJSON(ctx, a.sources.Helm, &releases, args...); err != nil {
+		return nil, err
+	}
+	var deploys []Deploy
+	for _, r := range releases {
+		updated, _ := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", r.Updated)
+		if updated.Before(since) && r.Status == "deployed" {
+			continue
+		}
+		version := fmt.Sprintf("revision %s, %s", r.Revision, r.Chart)
+		if r.AppVersion != "" {
+			version += " (app " + r.AppVersion + ")"
+		}
+		deploys = append(deploys, Deploy{
+			Source:      SourceHelm,
+			Name:        r.Name,
+			At:          updated.UTC(),
+			Version:     version,
+			Environment: r.Namespace,
+			Status:      r.Status,
+		})
+	}
+	return deploys, nil
+}
+
+// githubDeploys returns the GitHub deployments of the repository since
+// since, and the runs of its deploy and release workflows
+func (a *Agent) githubDeploys(ctx context.Context, since time.Time) ([]Deploy, error) {
+	repo := a.sources.GitHubRepo
+	var deployments []struct {
+		SHA         string    `json:"sha"`
+		Ref         string    `json:"ref"`
+		Environment string    `json:"environment"`
+		Description string    `json:"description"`
+		CreatedAt   time.Time `json:"created_at"`
+		Creator     struct {
+			Login string `json:"login"`
+		} `json:"creator"`
+	}
+	if err := runJSON(ctx, a.sources.GitHub, &deployments, "api", fmt.Sprintf("repos/%s/deployments?per_page=30", repo)); err != nil {
+		return nil, err
+	}
+	var deploys []Deploy
+	for _, d := range deployments {
+		if d.CreatedAt.Before(since) {
+			continue
+		}
+		name := d.Description
+		if name == "" {
+			name = repo
+		}
+		deploys = append(deploys, Deploy{
+			Source:      SourceGitHub,
+			Name:        name,
+			At:          d.CreatedAt.UTC(),
+			Version:     fmt.Sprintf("%s@%s", d.Ref, shortSHA(d.SHA)),
+			Environment: d.Environment,
+			Actor:       d.Creator.Login,
+		})
+	}
+
+	var runs []struct {
+		WorkflowName string    `json:"workflowName"`
+		HeadBranch   string    `json:"headBranch"`
+		HeadSHA      string    `json:"headSha"`
+		Status       string    `json:"status"`
+		Conclusion   string    `json:"conclusion"`
+		CreatedAt    time.Time `json:"createdAt"`
+	}
+	if err := runJSON(ctx, a.sources.GitHub, &runs, "run", "list", "-R", repo, "--limit", "30",
+		"--json", "workflowName,headBranch,headSha,status,conclusion,createdAt"); err != nil {
+		return nil, err
+	}
+	for _, r := range runs {
+		name := strings.ToLower(r.WorkflowName)
+		if r.CreatedAt.Before(since) || !(strings.Contains(name, "deploy") || strings.Contains(name, "release")) {
+			continue
+		}
+		status := r.Conclusion
+		if status == "" {
+			status = r.Status
+		}
+		deploys = append(deploys, Deploy{
+			Source:  SourceGitHub,
+			Name:    r.WorkflowName,
+			At:      r.CreatedAt.UTC(),
+			Version: fmt.Sprintf("%s@%s", r.HeadBranch, shortSHA(r.HeadSHA)),
+			Status:  status,
+		})
+	}
+	return deploys, nil
+}
+
+const edgeTrafficQuery = `query ($zoneTag: string, $since: Time, $until: Time) {
+  viewer {
+    zones(filter: {zoneTag: $zoneTag}) {
+      httpRequests1hGroups(limit: 48, filter: {datetime_geq: $since, datetime_lt: $until}, orderBy: [datetime_ASC]) {
+        dimensions { datetime }
+        sum { requests responseStatusMap { edgeResponseStatus requests } }
+      }
+    }
+  }
+}`
+
+// edgeAnomalies compares each zone's hourly traffic in the window with the
+// 24 hours before it and returns the zones whose 5xx rate or request
+// volume moved sharply
+func (a *Agent) edgeAnomalies(ctx context.Context, since, now time.Time) ([]EdgeAnomaly, error) {
+	var zones struct {
+		Result []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"result"`
+	}
+	if err := runJSON(ctx, a.sources.Cloudflare, &zones, "GET", "/zones?per_page=50"); err != nil {
+		return nil, err
+	}
+
+	windowStart := since.Truncate(time.Hour)
+	var anomalies []EdgeAnomaly
+	checked := 0
+	for _, zone := range zones.Result {
+		if len(a.sources.Zones) > 0 && !containsFold(a.sources.Zones, zone.Name) {
+			continue
+		}
+		if checked == maxZones {
+			break
+		}
+		checked++
+
+		body, err := json.Marshal(map[string]any{
+			"query": edgeTrafficQuery,
+			"variables": map[string]any{
+				"zoneTag": zone.ID,
+				"since":   windowStart.Add(-24 * time.Hour).UTC().Format(time.RFC3339),
+				"until":   now.UTC().Format(time.RFC3339),
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode traffic query: %w", err)
+		}
+		var resp struct {
+			Data struct {
+				Viewer struct {
+					Zones []struct {
+						Groups []struct {
+							Dimensions struct {
+								Datetime time.Time `json:"datetime"`
+							} `json:"dimensions"`
+							Sum struct {
+								Requests  int64 `json:"requests"`
+								StatusMap []struct {
+									Status   int   `json:"edgeResponseStatus"`
+									Requests int64 `json:"requests"`
+								} `json:"responseStatusMap"`
+							} `json:"sum"`
+						} `json:"httpRequests1hGroups"`
+					} `json:"zones"`
+				} `json:"viewer"`
+			} `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		if err := runJSON(ctx, a.sources.Cloudflare, &resp, "POST", "/graphql", string(body)); err != nil {
+			return nil, fmt.Errorf("zone %s: %w", zone.Name, err)
+		}
+		if len(resp.Errors) > 0 {
+			return nil, fmt.Errorf("zone %s: traffic query failed: %s", zone.Name, resp.Errors[0].Message)
+		}
+		if len(resp.Data.Viewer.Zones) == 0 {
+			continue
+		}
+
+		var cur, base struct {
+			hours           int
+			requests, err5x int64
+		}
+		for _, g := range resp.Data.Viewer.Zones[0].Groups {
+			var errs int64
+			for _, s := range g.Sum.StatusMap {
+				if s.Status >= 500 {
+					errs += s.Requests
+				}
+			}
+			bucket := &base
+			if !g.Dimensions.Datetime.Before(windowStart) {
+				bucket = &cur
+			}
+			bucket.hours++
+			bucket.requests += g.Sum.Requests
+			bucket.err5x += errs
+		}
+		if cur.hours == 0 || base.hours == 0 {
+			continue
+		}
+
+		anomaly := EdgeAnomaly{
+			Zone:             zone.Name,
+			Requests:         cur.requests,
+			Rate5xx:          ratio(cur.err5x, cur.requests),
+			BaselineRequests: float64(base.requests) / float64(base.hours),
+			BaselineRate5xx:  ratio(base.err5x, base.requests),
+		}
+		hourly := float64(cur.requests) / float64(cur.hours)
+		var reasons []string
+		if anomaly.Rate5xx >= 0.01 && anomaly.Rate5xx >= 2*anomaly.BaselineRate5xx {
+			reasons = append(reasons, fmt.Sprintf("5xx rate %.1f%% vs %.1f%% the day before", anomaly.Rate5xx*100, anomaly.BaselineRate5xx*100))
+		}
+		if anomaly.BaselineRequests >= 100 {
+			switch {
+			case hourly < 0.5*anomaly.BaselineRequests:
+				reasons = append(reasons, fmt.Sprintf("traffic down %.0f%%", (1-hourly/anomaly.BaselineRequests)*100))
+			case hourly > 3*anomaly.BaselineRequests:
+				reasons = append(reasons, fmt.Sprintf("traffic up %.1fx", hourly/anomaly.BaselineRequests))
+			}
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		anomaly.Reason = strings.Join(reasons, "; ")
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies, nil
+}
+
+// errorSpikes returns the unresolved Sentry issues seen in the window, most
+// frequent first
+func (a *Agent) errorSpikes(ctx context.Context, since, now time.Time) ([]ErrorSpike, error) {
+	minutes := int(now.Sub(since).Minutes())
+	issues, _, err := a.sources.Sentry.ListIssues(ctx, a.sources.SentryOrg, sentry.IssueListOptions{
+		Query:       fmt.Sprintf("is:unresolved lastSeen:-%dm", minutes),
+		StatsPeriod: "24h",
+		Sort:        "freq",
+		Limit:       maxErrors,
+	})
+	if err != nil {
+		return nil, err
+	}
+	spikes := make([]ErrorSpike, 0, len(issues))
+	for _, issue := range issues {
+		s := ErrorSpike{
+			ID:       issue.ShortID,
+			Title:    issue.Title,
+			Events:   issue.Count,
+			Users:    issue.UserCount,
+			New:      !issue.FirstSeen.Before(since),
+			LastSeen: issue.LastSeen.UTC(),
+			Link:     issue.Permalink,
+		}
+		if s.ID == "" {
+			s.ID = issue.ID
+		}
+		if issue.Project != nil {
+			s.Project = issue.Project.Slug
+		}
+		spikes = append(spikes, s)
+	}
+	return spikes, nil
+}
+
+// loadBalancer5xx returns the ALBs that returned 5xx in the window. When
+// some are named after service only those are checked.
+func (a *Agent) loadBalancer5xx(ctx context.Context, service string, since, now time.Time) ([]LoadBalancer5xx, error) {
+	var lbs []struct {
+		Name string `json:"name"`
+		ARN  string `json:"arn"`
+	}
+	if err := runJSON(ctx, a.sources.AWS, &lbs, "elbv2", "describe-load-balancers",
+		"--query", "LoadBalancers[?Type=='application'].{name:LoadBalancerName,arn:LoadBalancerArn}",
+		"--output", "json"); err != nil {
+		return nil, err
+	}
+	if service != "" {
+		var named = lbs[:0:0]
+		for _, lb := range lbs {
+			if strings.Contains(strings.ToLower(lb.Name), service) {
+				named = append(named, lb)
+			}
+		}
+		if len(named) > 0 {
+			lbs = named
+		}
+	}
+	if len(lbs) > maxLoadBalancers {
+		lbs = lbs[:maxLoadBalancers]
+	}
+
+	// One period covering the whole window, rounded up to CloudWatch's
+	// 60 second granularity
+	period := (int(now.Sub(since).Seconds()) + 59) / 60 * 60
+	var out []LoadBalancer5xx
+	for _, lb := range lbs {
+		_, dimension, ok := strings.Cut(lb.ARN, ":loadbalancer/")
+		if !ok {
+			continue
+		}
+		counts := map[string]int64{}
+		for _, metric := range []string{"RequestCount", "HTTPCode_ELB_5XX_Count", "HTTPCode_Target_5XX_Count"} {
+			var stats struct {
+				Datapoints []struct {
+					Sum float64 `json:"Sum"`
+				} `json:"Datapoints"`
+			}
+			if err := runJSON(ctx, a.sources.AWS, &stats, "cloudwatch", "get-metric-statistics",
+				"--namespace", "AWS/ApplicationELB",
+				"--metric-name", metric,
+				"--dimensions", "Name=LoadBalancer,Value="+dimension,
+				"--statistics", "Sum",
+				"--period", strconv.Itoa(period),
+				"--start-time", since.UTC().Format(time.RFC3339),
+				"--end-time", now.UTC().Format(time.RFC3339),
+				"--output", "json"); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", lb.Name, metric, err)
+			}
+			for _, dp := range stats.Datapoints {
+				counts[metric] += int64(dp.Sum)
+			}
+		}
+		l := LoadBalancer5xx{
+			Name:      lb.Name,
+			Requests:  counts["RequestCount"],
+			ELB5xx:    counts["HTTPCode_ELB_5XX_Count"],
+			Target5xx: counts["HTTPCode_Target_5XX_Count"],
+		}
+		if l.ELB5xx+l.Target5xx > 0 {
+			out = append(out, l)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].ELB5xx+out[i].Target5xx > out[j].ELB5xx+out[j].Target5xx
+	})
+	return out, nil
+}
+
+func ratio(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,398 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/gcp"
+	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Token exchange providers accepted by kubernetes.access.provider
+const (
+	AccessProviderEKS = "eks"
+	AccessProviderGKE = "gke"
+	AccessProviderAKS = "aks"
+)
+
+// aksServerAppID is the AAD application every AKS API server accepts
+// tokens for
+const aksServerAppID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+
+// execCredentialAPIVersion is the exec plugin protocol clanker speaks
+const execCredentialAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+// TokenAccess reaches a managed cluster with short-lived provider tokens
+// instead of a kubeconfig entry, so ~/.kube/config is never read or
+// modified. Useful for CI and shared machines.
+type TokenAccess struct {
+	Provider      string `json:"provider"` // eks, gke, or aks
+	Cluster       string `json:"cluster"`
+	Region        string `json:"region,omitempty"`  // EKS region or GKE location
+	Profile       string `json:"profile,omitempty"` // EKS: AWS profile
+	Project       string `json:"project,omitempty"` // GKE: project ID
+	ResourceGroup string `json:"resource_group,omitempty"`
+	Subscription  string `json:"subscription,omitempty"` // AKS
+}
+
+// AccessFromConfig reads the kubernetes.access section of the config file.
+// kubernetes.access.target ("eks/prod-cluster") sets provider and cluster
+// in one value and wins over the separate keys. It returns nil when token
+// access is not configured.
+func AccessFromConfig() (*TokenAccess, error) {
+	access := &TokenAccess{
+		Provider:      viper.GetString("kubernetes.access.provider"),
+		Cluster:       viper.GetString("kubernetes.access.cluster"),
+		Region:        viper.GetString("kubernetes.access.region"),
+		Profile:       viper.GetString("kubernetes.access.profile"),
+		Project:       viper.GetString("kubernetes.access.project"),
+		ResourceGroup: viper.GetString("kubernetes.access.resource_group"),
+		Subscription:  viper.GetString("kubernetes.access.subscription"),
+	}
+	if target := strings.TrimSpace(viper.GetString("kubernetes.access.target")); target != "" {
+		provider, cluster, ok := strings.Cut(target, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid access target %q (want provider/cluster, e.g. eks/prod)", target)
+		}
+		access.Provider, access.Cluster = provider, cluster
+	}
+	access.Provider = strings.ToLower(strings.TrimSpace(access.Provider))
+	access.Cluster = strings.TrimSpace(access.Cluster)
+	if access.Provider == "" && access.Cluster == "" {
+		return nil, nil
+	}
+	access.applyProviderDefaults()
+	if err := access.Validate(); err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+// applyProviderDefaults fills unset fields from the provider settings the
+// rest of clanker already uses
+func (a *TokenAccess) applyProviderDefaults() {
+	switch a.Provider {
+	case AccessProviderEKS:
+		for _, region := range []string{os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), viper.GetString("aws.default_region")} {
+			if a.Region == "" {
+				a.Region = strings.TrimSpace(region)
+			}
+		}
+	case AccessProviderGKE:
+		if a.Region == "" {
+			a.Region = viper.GetString("infra.gcp.region")
+		}
+		if a.Project == "" {
+			a.Project = gcp.ResolveProjectID()
+		}
+	case AccessProviderAKS:
+		if a.ResourceGroup == "" {
+			a.ResourceGroup = viper.GetString("infra.azure.resource_group")
+		}
+		if a.Subscription == "" {
+			a.Subscription = viper.GetString("infra.azure.subscription_id")
+		}
+	}
+}
+
+// Validate checks that the fields the provider needs are set
+func (a *TokenAccess) Validate() error {
+	if a.Cluster == "" {
+		return fmt.Errorf("token access requires a cluster name")
+	}
+	switch a.Provider {
+	case AccessProviderEKS:
+		if a.Region == "" {
+			return fmt.Errorf("eks token access requires a region")
+		}
+	case AccessProviderGKE:
+		if a.Region == "" || a.Project == "" {
+			return fmt.Errorf("gke token access requires a location (region) and project")
+		}
+	case AccessProviderAKS:
+		if a.ResourceGroup == "" {
+			return fmt.Errorf("aks token access requires a resource group")
+		}
+	default:
+		return fmt.Errorf("unsupported token access provider %q (use eks, gke, or aks)", a.Provider)
+	}
+	return nil
+}
+
+// key identifies the cluster for client caching and file names
+func (a *TokenAccess) key() string {
+	return a.Provider + "-" + a.Cluster
+}
+
+// runProviderCLI runs a provider CLI and returns stdout; swapped in tests
+var runProviderCLI = func(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+	if name == "gcloud" {
+		if bin, err := gcp.FindGcloudBinary(); err == nil {
+			name = bin
+		}
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w, stderr: %s", filepath.Base(name), strings.Join(args[:min(len(args), 3)], " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// awsEnv pins the AWS profile for the aws CLI calls
+func (a *TokenAccess) awsEnv() []string {
+	if a.Profile == "" {
+		return nil
+	}
+	return []string{"AWS_PROFILE=" + a.Profile}
+}
+
+// endpoint returns the API server URL and CA bundle for the cluster
+func (a *TokenAccess) endpoint(ctx context.Context) (string, []byte, error) {
+	switch a.Provider {
+	case AccessProviderEKS:
+		out, err := runProviderCLI(ctx, a.awsEnv(), "aws", "eks", "describe-cluster",
+			"--name", a.Cluster, "--region", a.Region, "--output", "json")
+		if err != nil {
+			return "", nil, err
+		}
+		var resp struct {
+			Cluster struct {
+				Endpoint             string `json:"endpoint"`
+				CertificateAuthority struct {
+					Data string `json:"data"`
+				} `json:"certificateAuthority"`
+			} `json:"cluster"`
+		}
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return "", nil, fmt.Errorf("failed to parse eks describe-cluster: %w", err)
+		}
+		return decodeEndpoint(resp.Cluster.Endpoint, resp.Cluster.CertificateAuthority.Data)
+
+	case AccessProviderGKE:
+		out, err := runProviderCLI(ctx, nil, "gcloud", "container", "clusters", "describe", a.Cluster,
+			"--location", a.Region, "--project", a.Project, "--format", "json")
+		if err != nil {
+			return "", nil, err
+		}
+		var resp struct {
+			Endpoint   string `json:"endpoint"`
+			MasterAuth struct {
+				ClusterCaCertificate string `json:"clusterCaCertificate"`
+			} `json:"masterAuth"`
+		}
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return "", nil, fmt.Errorf("failed to parse gke cluster description: %w", err)
+		}
+		return decodeEndpoint("https://"+resp.Endpoint, resp.MasterAuth.ClusterCaCertificate)
+
+	case AccessProviderAKS:
+		// --file - prints the kubeconfig instead of merging it into
+		// ~/.kube/config; only the cluster entry is kept
+		args := []string{"aks", "get-credentials", "--name", a.Cluster, "--resource-group", a.ResourceGroup, "--file", "-"}
+		if a.Subscription != "" {
+			args = append(args, "--subscription", a.Subscription)
+		}
+		out, err := runProviderCLI(ctx, nil, "az", args...)
+		if err != nil {
+			return "", nil, err
+		}
+		cfg, err := clientcmd.Load(out)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to parse aks credentials: %w", err)
+		}
+		kubeCtx, ok := cfg.Contexts[cfg.CurrentContext]
+		if !ok {
+			return "", nil, fmt.Errorf("aks credentials have no current context")
+		}
+		cluster, ok := cfg.Clusters[kubeCtx.Cluster]
+		if !ok || cluster.Server == "" {
+			return "", nil, fmt.Errorf("aks credentials have no cluster endpoint")
+		}
+		return cluster.Server, cluster.CertificateAuthorityData, nil
+	}
+	return "", nil, a.Validate()
+}
+
+func decodeEndpoint(server, caData string) (string, []byte, error) {
+	if server == "" || server == "https://" {
+		return "", nil, fmt.Errorf("cluster has no API endpoint yet")
+	}
+	ca, err := base64.StdEncoding.DecodeString(caData)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode cluster CA: %w", err)
+	}
+	return server, ca, nil
+}
+
+// execConfig returns the credential plugin that mints tokens. EKS uses
+// `aws eks get-token` directly; GKE and AKS run `clanker k8s token`, which
+// wraps the gcloud / az token commands in the exec plugin protocol.
+func (a *TokenAccess) execConfig() (*clientcmdapi.ExecConfig, error) {
+	cfg := &clientcmdapi.ExecConfig{
+		APIVersion:      execCredentialAPIVersion,
+		InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}
+	switch a.Provider {
+	case AccessProviderEKS:
+		cfg.Command = "aws"
+		cfg.Args = []string{"eks", "get-token", "--cluster-name", a.Cluster, "--region", a.Region, "--output", "json"}
+		if a.Profile != "" {
+			cfg.Env = []clientcmdapi.ExecEnvVar{{Name: "AWS_PROFILE", Value: a.Profile}}
+		}
+	case AccessProviderGKE, AccessProviderAKS:
+		self, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate clanker binary for token exchange: %w", err)
+		}
+		cfg.Command = self
+		cfg.Args = []string{"k8s", "token", "--provider", a.Provider}
+		if a.Subscription != "" {
+			cfg.Args = append(cfg.Args, "--subscription", a.Subscription)
+		}
+	default:
+		return nil, a.Validate()
+	}
+	return cfg, nil
+}
+
+// RESTConfig builds an in-memory client-go config for the cluster. Tokens
+// come from the exec plugin, so client-go refreshes them as they expire.
+func (a *TokenAccess) RESTConfig(ctx context.Context) (*rest.Config, error) {
+	server, ca, err := a.endpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	execCfg, err := a.execConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &rest.Config{
+		Host:            server,
+		TLSClientConfig: rest.TLSClientConfig{CAData: ca},
+		ExecProvider:    execCfg,
+	}, nil
+}
+
+// WriteKubeconfig writes a kubeconfig for kubectl and helm under
+// ~/.clanker/kube. It holds only the endpoint, CA, and exec plugin, never a
+// token, and is separate from ~/.kube/config.
+func (a *TokenAccess) WriteKubeconfig(ctx context.Context) (string, error) {
+	restCfg, err := a.RESTConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	dir := filepath.Join(home, ".clanker", "kube")
+	if err := secfile.EnsurePrivateDir(dir); err != nil {
+		return "", err
+	}
+
+	name := a.key()
+	cfg := clientcmdapi.NewConfig()
+	cfg.Clusters[name] = &clientcmdapi.Cluster{Server: restCfg.Host, CertificateAuthorityData: restCfg.CAData}
+	cfg.AuthInfos[name] = &clientcmdapi.AuthInfo{Exec: restCfg.ExecProvider}
+	cfg.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	cfg.CurrentContext = name
+	data, err := clientcmd.Write(*cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode kubeconfig: %w", err)
+	}
+
+	path := filepath.Join(dir, secfile.SafeSlug(name)+".yaml")
+	if err := secfile.WritePrivate(path, data); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ExecCredential is the exec plugin response kubectl and client-go expect
+type ExecCredential struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Status     ExecCredentialStatus `json:"status"`
+}
+
+// ExecCredentialStatus carries the bearer token and its expiry
+type ExecCredentialStatus struct {
+	Token               string    `json:"token"`
+	ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+}
+
+// ProviderToken exchanges the ambient gcloud or az login for a cluster
+// bearer token. subscription selects the az account and may be empty.
+func ProviderToken(ctx context.Context, provider, subscription string) (*ExecCredential, error) {
+	cred := &ExecCredential{APIVersion: execCredentialAPIVersion, Kind: "ExecCredential"}
+	switch strings.ToLower(provider) {
+	case AccessProviderGKE:
+		out, err := runProviderCLI(ctx, nil, "gcloud", "config", "config-helper", "--format", "json")
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Credential struct {
+				AccessToken string    `json:"access_token"`
+				TokenExpiry time.Time `json:"token_expiry"`
+			} `json:"credential"`
+		}
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse gcloud credentials: %w", err)
+		}
+		cred.Status = ExecCredentialStatus{Token: resp.Credential.AccessToken, ExpirationTimestamp: resp.Credential.TokenExpiry}
+
+	case AccessProviderAKS:
+		args := []string{"account", "get-access-token", "--resource", aksServerAppID, "--output", "json"}
+		if subscription != "" {
+			args = append(args, "--subscription", subscription)
+		}
+		out, err := runProviderCLI(ctx, nil, "az", args...)
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			AccessToken string          `json:"accessToken"`
+			ExpiresOn   json.RawMessage `json:"expires_on"`
+		}
+		if err := json.Unmarshal(out, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse az access token: %w", err)
+		}
+		cred.Status = ExecCredentialStatus{Token: resp.AccessToken, ExpirationTimestamp: azExpiry(resp.ExpiresOn)}
+
+	default:
+		return nil, fmt.Errorf("unsupported token provider %q (use gke or aks)", provider)
+	}
+
+	if cred.Status.Token == "" {
+		return nil, fmt.Errorf("%s returned an empty token; log in with the provider CLI first", provider)
+	}
+	return cred, nil
+}
+
+// azExpiry reads az's expires_on, a unix timestamp that older releases
+// quote as a string. Missing values fall back to a conservative 30 minutes.
+func azExpiry(raw json.RawMessage) time.Time {
+	value := strings.Trim(string(raw), `"`)
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil && secs > 0 {
+		return time.Unix(secs, 0).UTC()
+	}
+	return time.Now().Add(30 * time.Minute).UTC()
+}
@@ -0,0 +1,203 @@
+package k8s
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// stubProviderCLI answers provider CLI calls by "name subcommand" and
+// records every invocation
+func stubProviderCLI(t *testing.T, responses map[string]string) *[]string {
+	t.Helper()
+	var calls []string
+	orig := runProviderCLI
+	runProviderCLI = func(ctx context.Context, env []string, name string, args ...string) ([]byte, error) {
+		call := strings.TrimSpace(strings.Join(env, " ") + " " + name + " " + strings.Join(args, " "))
+		calls = append(calls, call)
+		for prefix, out := range responses {
+			if strings.HasPrefix(name+" "+strings.Join(args, " "), prefix) {
+				return []byte(out), nil
+			}
+		}
+		return nil, fmt.Errorf("unexpected call: %s", call)
+	}
+	t.Cleanup(func() { runProviderCLI = orig })
+	return &calls
+}
+
+func TestAccessFromConfig(t *testing.T) {
+	t.Cleanup(func() {
+		viper.Set("kubernetes.access.target", "")
+		viper.Set("kubernetes.access.profile", "")
+	})
+
+	if access, err := AccessFromConfig(); err != nil || access != nil {
+		t.Fatalf("unconfigured access = %+v, %v", access, err)
+	}
+
+	t.Setenv("AWS_REGION", "eu-west-1")
+	viper.Set("kubernetes.access.target", "EKS/prod")
+	viper.Set("kubernetes.access.profile", "ci")
+	access, err := AccessFromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &TokenAccess{Provider: "eks", Cluster: "prod", Region: "eu-west-1", Profile: "ci"}
+	if !reflect.DeepEqual(access, want) {
+		t.Errorf("access = %+v, want %+v", access, want)
+	}
+
+	viper.Set("kubernetes.access.target", "aks/prod")
+	if _, err := AccessFromConfig(); err == nil || !strings.Contains(err.Error(), "resource group") {
+		t.Errorf("aks without resource group: err = %v", err)
+	}
+	viper.Set("kubernetes.access.target", "prod")
+	if _, err := AccessFromConfig(); err == nil {
+		t.Error("expected error for target without provider")
+	}
+}
+
+func TestEKSRESTConfig(t *testing.T) {
+	ca := base64.StdEncoding.EncodeToString([]byte("test-ca"))
+	calls := stubProviderCLI(t, map[string]string{
+		"aws eks describe-cluster": `{"cluster": {"endpoint": "https://ABC.gr7.eu-west-1.eks.amazonaws.com",
+			"certificateAuthority": {"data": "` + ca + `"}}}`,
+	})
+
+	access := &TokenAccess{Provider: AccessProviderEKS, Cluster: "prod", Region: "eu-west-1", Profile: "ci"}
+	cfg, err := access.RESTConfig(context.Background())
+	if err != nil {
+		t.Fatalf("RESTConfig: %v", err)
+	}
+	if cfg.Host != "https://ABC.gr7.eu-west-1.eks.amazonaws.com" || string(cfg.CAData) != "test-ca" {
+		t.Errorf("host = %q, ca = %q", cfg.Host, cfg.CAData)
+	}
+	if cfg.BearerToken != "" {
+		t.Error("the REST config should mint tokens through the exec plugin, not embed one")
+	}
+	exec := cfg.ExecProvider
+	if exec.Command != "aws" || !reflect.DeepEqual(exec.Args, []string{"eks", "get-token", "--cluster-name", "prod", "--region", "eu-west-1", "--output", "json"}) {
+		t.Errorf("exec = %s %v", exec.Command, exec.Args)
+	}
+	if len(exec.Env) != 1 || exec.Env[0].Value != "ci" {
+		t.Errorf("exec env = %+v", exec.Env)
+	}
+	if len(*calls) != 1 || !strings.HasPrefix((*calls)[0], "AWS_PROFILE=ci aws eks describe-cluster --name prod") {
+		t.Errorf("calls = %q", *calls)
+	}
+}
+
+func TestAKSEndpointKeepsOnlyClusterEntry(t *testing.T) {
+	ca := base64.StdEncoding.EncodeToString([]byte("aks-ca"))
+	stubProviderCLI(t, map[string]string{
+		"az aks get-credentials": `apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://prod-dns.hcp.westeurope.azmk8s.io:443
+    certificate-authority-data: ` + ca + `
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: clusterUser_rg_prod
+current-context: prod
+users:
+- name: clusterUser_rg_prod
+  user:
+    token: should-not-be-used
+`,
+	})
+
+	access := &TokenAccess{Provider: AccessProviderAKS, Cluster: "prod", ResourceGroup: "rg"}
+	cfg, err := access.RESTConfig(context.Background())
+	if err != nil {
+		t.Fatalf("RESTConfig: %v", err)
+	}
+	if cfg.Host != "https://prod-dns.hcp.westeurope.azmk8s.io:443" || string(cfg.CAData) != "aks-ca" || cfg.BearerToken != "" {
+		t.Errorf("config = host %q ca %q token %q", cfg.Host, cfg.CAData, cfg.BearerToken)
+	}
+	if cfg.ExecProvider.Args[len(cfg.ExecProvider.Args)-1] != AccessProviderAKS {
+		t.Errorf("exec args = %v", cfg.ExecProvider.Args)
+	}
+}
+
+func TestProviderToken(t *testing.T) {
+	stubProviderCLI(t, map[string]string{
+		"gcloud config config-helper": `{"credential": {"access_token": "ya29.token", "token_expiry": "2030-01-02T03:04:05Z"}}`,
+		"az account get-access-token": `{"accessToken": "eyJ.token", "expires_on": "1893456000"}`,
+	})
+
+	gke, err := ProviderToken(context.Background(), "gke", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gke.Kind != "ExecCredential" || gke.Status.Token != "ya29.token" || gke.Status.ExpirationTimestamp.Year() != 2030 {
+		t.Errorf("gke credential = %+v", gke)
+	}
+
+	aks, err := ProviderToken(context.Background(), "aks", "sub-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aks.Status.Token != "eyJ.token" || !aks.Status.ExpirationTimestamp.Equal(time.Unix(1893456000, 0)) {
+		t.Errorf("aks credential = %+v", aks)
+	}
+
+	if _, err := ProviderToken(context.Background(), "eks", ""); err == nil {
+		t.Error("eks tokens come from aws eks get-token, not ProviderToken")
+	}
+}
+
+func TestClientWithTokenAccessUsesGeneratedKubeconfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ca := base64.StdEncoding.EncodeToString([]byte("test-ca"))
+	stubProviderCLI(t, map[string]string{
+		"aws eks describe-cluster": `{"cluster": {"endpoint": "https://eks.example", "certificateAuthority": {"data": "` + ca + `"}}}`,
+	})
+
+	client := NewClientWithTokenAccess(&TokenAccess{Provider: AccessProviderEKS, Cluster: "prod", Region: "us-east-1"}, false)
+	client.SetContext("ignored")
+	if err := client.prepareAccess(context.Background()); err != nil {
+		t.Fatalf("prepareAccess: %v", err)
+	}
+
+	want := filepath.Join(os.Getenv("HOME"), ".clanker", "kube", "eks-prod.yaml")
+	args := client.buildArgs("default", []string{"get", "pods"})
+	if !reflect.DeepEqual(args, []string{"--kubeconfig", want, "-n", "default", "get", "pods"}) {
+		t.Errorf("args = %v", args)
+	}
+	data, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "get-token") || strings.Contains(string(data), "token:") {
+		t.Errorf("kubeconfig should reference the exec plugin and hold no token:\n%s", data)
+	}
+	if info, _ := os.Stat(want); info.Mode().Perm() != 0o600 {
+		t.Errorf("kubeconfig mode = %v", info.Mode().Perm())
+	}
+}
+
+func TestNewClientSurfacesBadAccessConfig(t *testing.T) {
+	viper.Set("kubernetes.access.target", "doks/prod")
+	t.Cleanup(func() { viper.Set("kubernetes.access.target", "") })
+
+	client := NewClient("", "", false)
+	if _, err := client.Run(context.Background(), "get", "pods"); err == nil || !strings.Contains(err.Error(), "unsupported token access provider") {
+		t.Errorf("Run err = %v", err)
+	}
+	if explicit := NewClient("/tmp/kubeconfig", "", false); explicit.TokenAccess() != nil || explicit.accessErr != nil {
+		t.Error("an explicit kubeconfig should win over token access")
+	}
+}
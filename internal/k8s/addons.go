@@ -0,0 +1,146 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s/cluster"
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+)
+
+// AddonIntent is a parsed "install the EBS CSI driver on cluster X" request
+type AddonIntent struct {
+	ClusterName string
+	Addon       cluster.EKSAddonSpec
+	Version     string // empty for the default or newest version
+	Upgrade     bool
+}
+
+var (
+	addonVerbPattern    = regexp.MustCompile(`\b(install|add|enable|upgrade|update)\b`)
+	addonVersionPattern = regexp.MustCompile(`\bv\d+\.\d+\.\d+(?:-eksbuild\.\d+)?\b`)
+)
+
+// ParseAddonQuery recognizes EKS add-on install and upgrade requests for
+// the add-ons in the catalog. Queries must name the cluster, EKS or the word
+// add-on so that "install metrics-server with helm" stays with the helm
+// sub-agent.
+func ParseAddonQuery(query string) (AddonIntent, bool) {
+	q := strings.ToLower(query)
+	verb := addonVerbPattern.FindString(q)
+	if verb == "" || strings.Contains(q, "helm") {
+		return AddonIntent{}, false
+	}
+	if !containsAny(q, []string{"cluster", "eks", "add-on", "addon"}) {
+		return AddonIntent{}, false
+	}
+
+	spec, ok := findCatalogAddon(q)
+	if !ok {
+		return AddonIntent{}, false
+	}
+
+	intent := AddonIntent{
+		Addon:   spec,
+		Version: addonVersionPattern.FindString(q),
+		Upgrade: verb == "upgrade" || verb == "update",
+	}
+	for _, pattern := range []*regexp.Regexp{upgradeClusterNamePattern, upgradeNameClusterPattern} {
+		if m := pattern.FindStringSubmatch(q); m != nil && !upgradeNameStopWords[m[1]] {
+			intent.ClusterName = m[1]
+			break
+		}
+	}
+	return intent, true
+}
+
+// findCatalogAddon matches add-on names and aliases as whole words. Three
+// letter aliases ("ebs", "cni") are too ambiguous in free text and are only
+// accepted by `clanker k8s addon`.
+func findCatalogAddon(q string) (cluster.EKSAddonSpec, bool) {
+	for _, spec := range cluster.EKSAddonCatalog() {
+		names := append([]string{spec.Name}, spec.Aliases...)
+		for _, name := range names {
+			if len(name) <= 3 {
+				continue
+			}
+			pattern := `(^|[^a-z0-9-])` + regexp.QuoteMeta(name) + `($|[^a-z0-9-])`
+			if regexp.MustCompile(pattern).MatchString(q) {
+				return spec, true
+			}
+		}
+	}
+	return cluster.EKSAddonSpec{}, false
+}
+
+// generateAddonPlan turns an add-on request into the install or upgrade
+// steps, with IRSA role creation for add-ons that call AWS APIs
+func (a *Agent) generateAddonPlan(query string, opts QueryOptions, k8sPlan *K8sPlan) (*K8sPlan, error) {
+	intent, _ := ParseAddonQuery(query)
+	if intent.ClusterName == "" {
+		intent.ClusterName = opts.ClusterName
+	}
+	k8sPlan.ClusterType = ClusterTypeEKS
+	if intent.ClusterName != "" {
+		k8sPlan.ClusterName = intent.ClusterName
+	}
+
+	if opts.ClusterType != "" && opts.ClusterType != ClusterTypeEKS {
+		k8sPlan.Summary = fmt.Sprintf("Install %s", intent.Addon.Name)
+		k8sPlan.Warnings = append(k8sPlan.Warnings,
+			fmt.Sprintf("EKS add-ons only exist on EKS clusters; install %s with helm on %s clusters", intent.Addon.Name, opts.ClusterType))
+		return k8sPlan, nil
+	}
+
+	name := intent.ClusterName
+	if name == "" {
+		name = "<cluster>"
+		k8sPlan.Warnings = append(k8sPlan.Warnings, "Cluster name not found in the request")
+	}
+
+	addonPlan := plan.GenerateAddonPlan(plan.AddonOptions{
+		ClusterName:    name,
+		Addon:          intent.Addon.Name,
+		Version:        intent.Version,
+		Upgrade:        intent.Upgrade,
+		Namespace:      intent.Addon.Namespace,
+		ServiceAccount: intent.Addon.ServiceAccount,
+		PolicyARN:      intent.Addon.PolicyARN,
+	})
+
+	k8sPlan.Summary = addonPlan.Summary
+	appendBootstrapSteps(k8sPlan, addonPlan.Steps, string(ClusterTypeEKS), name)
+	k8sPlan.Notes = append(k8sPlan.Notes, addonPlan.Notes...)
+
+	verb := "install"
+	if intent.Upgrade {
+		verb = "upgrade"
+	}
+	apply := fmt.Sprintf("Apply with: clanker k8s addon %s %s %s", verb, name, intent.Addon.Name)
+	if intent.Addon.NeedsIRSA() {
+		apply += " --service-account-role-arn <ROLE_ARN>"
+	}
+	k8sPlan.Notes = append(k8sPlan.Notes, apply)
+
+	return k8sPlan, nil
+}
+
+// appendBootstrapSteps adds generated plan steps as bootstrap commands,
+// labelled by phase when the plan has phases
+func appendBootstrapSteps(k8sPlan *K8sPlan, steps []plan.Step, clusterType, target string) {
+	for _, step := range steps {
+		operation := step.Phase
+		if operation == "" {
+			operation = step.ID
+		}
+		k8sPlan.Bootstrap = append(k8sPlan.Bootstrap, BootstrapCommand{
+			Type:      clusterType,
+			Operation: operation,
+			Target:    target,
+			Command:   strings.TrimSpace(step.Command + " " + strings.Join(step.Args, " ")),
+			Reason:    step.Description,
+			Produces:  step.Produces,
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseAddonQuery(t *testing.T) {
+	tests := []struct {
+		query       string
+		ok          bool
+		addon       string
+		clusterName string
+		version     string
+		upgrade     bool
+	}{
+		{query: "install the EBS CSI driver on cluster prod", ok: true, addon: "aws-ebs-csi-driver", clusterName: "prod"},
+		{query: "add metrics-server to the staging eks cluster", ok: true, addon: "metrics-server", clusterName: "staging"},
+		{query: "upgrade the coredns addon on cluster prod to v1.11.1-eksbuild.9", ok: true, addon: "coredns", clusterName: "prod", version: "v1.11.1-eksbuild.9", upgrade: true},
+		{query: "update vpc-cni on cluster dev", ok: true, addon: "vpc-cni", clusterName: "dev", upgrade: true},
+		{query: "install metrics-server with helm on cluster prod", ok: false},
+		{query: "install metrics-server", ok: false},
+		{query: "upgrade eks cluster prod to 1.30", ok: false},
+		{query: "list add-ons on cluster prod", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseAddonQuery(tt.query)
+		if ok != tt.ok {
+			t.Errorf("ParseAddonQuery(%q) ok = %v, want %v", tt.query, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if got.Addon.Name != tt.addon || got.ClusterName != tt.clusterName || got.Version != tt.version || got.Upgrade != tt.upgrade {
+			t.Errorf("ParseAddonQuery(%q) = {addon=%s cluster=%s version=%s upgrade=%v}, want {%s %s %s %v}",
+				tt.query, got.Addon.Name, got.ClusterName, got.Version, got.Upgrade,
+				tt.addon, tt.clusterName, tt.version, tt.upgrade)
+		}
+	}
+}
+
+func TestAddonQueryRoutesBeforeUpgrade(t *testing.T) {
+	a := &Agent{}
+	if got := a.analyzeQuery("upgrade the coredns addon on cluster prod to v1.11.1").Category; got != "cluster_addon" {
+		t.Errorf("category = %q, want cluster_addon", got)
+	}
+}
+
+func TestGenerateAddonPlanIncludesIRSA(t *testing.T) {
+	a := &Agent{}
+	query := "install the EBS CSI driver on cluster prod"
+	plan, err := a.generatePlan(context.Background(), query, a.analyzeQuery(query), QueryOptions{})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+
+	var ops []string
+	for _, cmd := range plan.Bootstrap {
+		ops = append(ops, cmd.Operation)
+	}
+	joined := strings.Join(ops, ",")
+	for _, want := range []string{"associate-oidc-provider", "create-irsa-role", "attach-role-policy", "create-addon"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("plan steps %v missing %s", ops, want)
+		}
+	}
+	if strings.Index(joined, "create-irsa-role") > strings.Index(joined, "create-addon") {
+		t.Errorf("IRSA role must be created before the add-on: %v", ops)
+	}
+
+	var apply string
+	for _, note := range plan.Notes {
+		if strings.HasPrefix(note, "Apply with:") {
+			apply = note
+		}
+	}
+	if apply != "Apply with: clanker k8s addon install prod aws-ebs-csi-driver --service-account-role-arn <ROLE_ARN>" {
+		t.Errorf("apply note = %q", apply)
+	}
+}
+
+func TestGenerateAddonPlanNonEKS(t *testing.T) {
+	a := &Agent{}
+	query := "install metrics-server addon on cluster prod"
+	plan, err := a.generatePlan(context.Background(), query, a.analyzeQuery(query), QueryOptions{ClusterType: ClusterTypeGKE})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+	if len(plan.Bootstrap) != 0 || len(plan.Warnings) == 0 {
+		t.Errorf("expected a warning and no steps, got %+v", plan)
+	}
+}
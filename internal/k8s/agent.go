@@ -4,21 +4,36 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bgdnvk/clanker/internal/cli"
+	"github.com/bgdnvk/clanker/internal/dryrun"
+	"github.com/bgdnvk/clanker/internal/k8s/backup"
 	"github.com/bgdnvk/clanker/internal/k8s/cluster"
 	"github.com/bgdnvk/clanker/internal/k8s/helm"
+	"github.com/bgdnvk/clanker/internal/k8s/manifestgen"
 	"github.com/bgdnvk/clanker/internal/k8s/networking"
+	"github.com/bgdnvk/clanker/internal/k8s/openshift"
+	"github.com/bgdnvk/clanker/internal/k8s/rbac"
 	"github.com/bgdnvk/clanker/internal/k8s/sre"
 	"github.com/bgdnvk/clanker/internal/k8s/storage"
 	"github.com/bgdnvk/clanker/internal/k8s/telemetry"
 	"github.com/bgdnvk/clanker/internal/k8s/workloads"
+	"github.com/bgdnvk/clanker/internal/qfilter"
 	"github.com/bgdnvk/clanker/internal/verda"
+	"golang.org/x/sync/errgroup"
 )
 
 // Agent is the main K8s orchestrator that receives delegated queries from the main agent
 type Agent struct {
+	// mu guards the lazily created client and sub-agents and the detected
+	// cloud provider, so HandleQuery can run from several goroutines
+	mu sync.Mutex
+
 	client        *Client
 	clusterMgr    *cluster.Manager
 	workloads     *workloads.SubAgent
@@ -27,9 +42,27 @@ type Agent struct {
 	helm          *helm.SubAgent
 	sre           *sre.SubAgent
 	telemetry     *telemetry.SubAgent
+	rbac          *rbac.SubAgent
+	openshift     *openshift.SubAgent
+	backup        *backup.SubAgent
 	debug         bool
 	aiDecisionFn  AIDecisionFunc
+	queryModelFn  AIDecisionFunc
 	cloudProvider CloudProvider
+	errorTracker  sre.ErrorTracker
+
+	// clientKey is the kubeconfig and context client was created for
+	clientKey contextKey
+	// contexts holds an agent with its own client and sub-agents for each
+	// other kubeconfig and context a query names; parent links them back
+	contexts map[contextKey]*Agent
+	parent   *Agent
+}
+
+// contextKey names the kubeconfig and context a client runs against
+type contextKey struct {
+	kubeconfig string
+	context    string
 }
 
 // AgentOptions contains options for creating a K8s agent
@@ -70,19 +103,13 @@ func NewAgentWithOptions(opts AgentOptions) *Agent {
 	verdaClientSecret := verda.ResolveClientSecret()
 	switch {
 	case verdaClientID == "" && verdaClientSecret == "":
-		if opts.Debug {
-			fmt.Println("[k8s] verda provider skipped: no verda credentials configured")
-		}
+		logger.Debug("verda provider skipped: no verda credentials configured")
 	case verdaClientID == "" || verdaClientSecret == "":
-		if opts.Debug {
-			fmt.Println("[k8s] verda provider skipped: partial credentials (need both client_id and client_secret)")
-		}
+		logger.Debug("verda provider skipped: partial credentials (need both client_id and client_secret)")
 	default:
 		client, err := verda.NewClient(verdaClientID, verdaClientSecret, verda.ResolveProjectID(), opts.Debug)
 		if err != nil {
-			if opts.Debug {
-				fmt.Printf("[k8s] verda provider skipped: %v\n", err)
-			}
+			logger.Debugf("verda provider skipped: %v", err)
 		} else {
 			mgr.RegisterProvider(cluster.NewVerdaInstantProvider(cluster.VerdaInstantProviderOptions{
 				Client:          client,
@@ -91,9 +118,7 @@ func NewAgentWithOptions(opts AgentOptions) *Agent {
 				SSHKeyPath:      verda.ResolveSSHKeyPath(),
 				Debug:           opts.Debug,
 			}))
-			if opts.Debug {
-				fmt.Println("[k8s] verda-instant provider registered")
-			}
+			logger.Debug("verda-instant provider registered")
 		}
 	}
 
@@ -121,6 +146,9 @@ func (a *Agent) RegisterKubeadmProvider(opts KubeadmProviderOptions) {
 		SubnetID:    opts.SubnetID,
 		KeyPairName: opts.KeyPairName,
 		SSHKeyPath:  opts.SSHKeyPath,
+		SSHUser:     opts.SSHUser,
+		JumpHost:    opts.JumpHost,
+		Backend:     opts.Backend,
 		Debug:       a.debug,
 	}))
 }
@@ -146,16 +174,164 @@ type KubeadmProviderOptions struct {
 	SubnetID    string
 	KeyPairName string
 	SSHKeyPath  string
+	// SSHUser overrides the backend's default login user
+	SSHUser string
+	// JumpHost is a bastion ([user@]host[:port]) for nodes in private subnets
+	JumpHost string
+	// Backend overrides the default EC2 backend (e.g. Hetzner Cloud)
+	Backend cluster.InstanceBackend
 }
 
 // SetAIDecisionFunction sets the function used for AI based decisions
 func (a *Agent) SetAIDecisionFunction(fn AIDecisionFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	a.aiDecisionFn = fn
+	for _, scoped := range a.contexts {
+		scoped.SetAIDecisionFunction(fn)
+	}
+}
+
+// SetQueryModel sets the model used to classify questions the category
+// rules are unsure of and to read namespaces, names and selectors from
+// them, without having plans generated by AI. Without one the AI decision
+// function is used, then the rules and patterns alone.
+func (a *Agent) SetQueryModel(fn AIDecisionFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.queryModelFn = fn
+	for _, scoped := range a.contexts {
+		scoped.SetQueryModel(fn)
+	}
 }
 
-// SetClient sets the kubectl client
+// SetEntityExtractor sets the model used to read namespaces, names and
+// selectors from questions.
+//
+// Deprecated: use SetQueryModel, which also classifies questions.
+func (a *Agent) SetEntityExtractor(fn AIDecisionFunc) {
+	a.SetQueryModel(fn)
+}
+
+// queryModel returns the model that reads questions, or nil
+func (a *Agent) queryModel() AIDecisionFunc {
+	if a.queryModelFn != nil {
+		return a.queryModelFn
+	}
+	return a.aiDecisionFn
+}
+
+// SetErrorTracker has SRE diagnoses of a named workload include the
+// application errors tracker has seen from it
+func (a *Agent) SetErrorTracker(tracker sre.ErrorTracker) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.errorTracker = tracker
+	if a.sre != nil {
+		a.sre.SetErrorTracker(tracker)
+	}
+	for _, scoped := range a.contexts {
+		scoped.SetErrorTracker(tracker)
+	}
+}
+
+// SetClient sets the kubectl client. Call it before the first query:
+// sub-agents already created keep the client they were given.
 func (a *Agent) SetClient(client *Client) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	a.client = client
+	a.clientKey = contextKey{kubeconfig: client.kubeconfig, context: client.context}
+}
+
+// ensureClient creates the client and sub-agents on first use and returns
+// the agent to answer a query with. A query naming another kubeconfig or
+// context than the client's gets an agent of its own for it, kept for
+// later queries, so queries running at the same time never switch the
+// cluster another one is using.
+func (a *Agent) ensureClient(opts QueryOptions) *Agent {
+	if a.parent != nil {
+		return a.parent.ensureClient(opts)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.client == nil {
+		a.client = NewClient(opts.Kubeconfig, opts.Context, a.debug)
+		a.clientKey = contextKey{kubeconfig: opts.Kubeconfig, context: opts.Context}
+	}
+	a.ensureSubAgents()
+
+	// A query naming no kubeconfig means the client's kubeconfig, and one
+	// naming no context there means the client's context. Naming another
+	// kubeconfig without a context means that kubeconfig's current context.
+	key := contextKey{kubeconfig: opts.Kubeconfig, context: opts.Context}
+	if key.kubeconfig == "" {
+		key.kubeconfig = a.clientKey.kubeconfig
+	}
+	if key.context == "" && key.kubeconfig == a.clientKey.kubeconfig {
+		key.context = a.clientKey.context
+	}
+	if key == a.clientKey {
+		return a
+	}
+	if scoped, ok := a.contexts[key]; ok {
+		return scoped
+	}
+
+	scoped := &Agent{
+		client:        NewClient(key.kubeconfig, key.context, a.debug),
+		clientKey:     key,
+		clusterMgr:    a.clusterMgr,
+		debug:         a.debug,
+		aiDecisionFn:  a.aiDecisionFn,
+		queryModelFn:  a.queryModelFn,
+		cloudProvider: a.cloudProvider,
+		errorTracker:  a.errorTracker,
+		parent:        a,
+	}
+	scoped.ensureSubAgents()
+	if a.contexts == nil {
+		a.contexts = make(map[contextKey]*Agent)
+	}
+	a.contexts[key] = scoped
+	return scoped
+}
+
+// ensureSubAgents creates the sub-agents that are still nil around the
+// client. The caller holds a.mu or has not shared a yet.
+func (a *Agent) ensureSubAgents() {
+	if a.workloads == nil {
+		a.workloads = workloads.NewSubAgent(&clientAdapter{client: a.client}, a.debug)
+	}
+	if a.networking == nil {
+		a.networking = networking.NewSubAgent(&networkingClientAdapter{client: a.client}, a.debug)
+	}
+	if a.storage == nil {
+		a.storage = storage.NewSubAgent(&storageClientAdapter{client: a.client}, a.debug)
+	}
+	if a.helm == nil {
+		a.helm = helm.NewSubAgent(&helmClientAdapter{client: a.client}, a.debug)
+	}
+	if a.sre == nil {
+		a.sre = sre.NewSubAgent(&sreClientAdapter{client: a.client}, a.debug)
+		if a.errorTracker != nil {
+			a.sre.SetErrorTracker(a.errorTracker)
+		}
+	}
+	if a.telemetry == nil {
+		a.telemetry = telemetry.NewSubAgent(&telemetryClientAdapter{client: a.client}, a.debug)
+	}
+	if a.rbac == nil {
+		a.rbac = rbac.NewSubAgent(&rbacClientAdapter{client: a.client}, a.debug)
+	}
+	if a.openshift == nil {
+		a.openshift = openshift.NewSubAgent(&openshiftClientAdapter{client: a.client}, a.debug)
+	}
+	if a.backup == nil {
+		a.backup = backup.NewSubAgent(&backupClientAdapter{client: a.client}, a.debug)
+	}
 }
 
 // EnsureDependencies checks and optionally installs missing CLI tools
@@ -164,9 +340,7 @@ func (a *Agent) EnsureDependencies(ctx context.Context) error {
 	missing := checker.CheckMissing()
 
 	if len(missing) == 0 {
-		if a.debug {
-			fmt.Println("[k8s-agent] All CLI dependencies are satisfied")
-		}
+		logger.Debug("All CLI dependencies are satisfied")
 		return nil
 	}
 
@@ -240,53 +414,88 @@ func (a *Agent) GetClusterProvider(clusterType ClusterType) (cluster.Provider, b
 	return a.clusterMgr.GetProvider(clusterType)
 }
 
-// HandleQuery processes a K8s related query delegated from the main agent
+// HandleQuery processes a K8s related query delegated from the main agent.
+// Plans from any sub-agent are checked against the operator policy so the
+// preview shows what an apply would refuse.
 func (a *Agent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*K8sResponse, error) {
-	if a.debug {
-		fmt.Printf("[k8s-agent] handling query: %s\n", query)
+	logger.Debugf("handling query: %s", query)
+
+	a = a.ensureClient(opts)
+
+	// Context switches and cross-context comparisons run directly. A
+	// switch changes the kubeconfig's current context, which queries naming
+	// no context follow, through a client of its own: the shared one may be
+	// running other queries.
+	if intent, ok := ParseContextQuery(query); ok {
+		client := a.client
+		switch intent.Action {
+		case ContextActionSwitch:
+			client = NewClient(a.clientKey.kubeconfig, "", a.debug)
+		case ContextActionCompare:
+			if intent.Namespace == "" {
+				intent.Namespace = opts.Namespace
+			}
+		}
+		result, err := ExecuteContextIntent(ctx, client, intent)
+		if err != nil {
+			return nil, err
+		}
+		return &K8sResponse{Type: ResponseTypeResult, Result: result}, nil
 	}
 
-	// Initialize client if needed
-	if a.client == nil {
-		a.client = NewClient(opts.Kubeconfig, "", a.debug)
+	// Without a current context every kubectl call fails; ask which
+	// cluster the query is for rather than picking one
+	kubeContext, clarification := a.resolveContext(ctx, opts)
+	if clarification != nil {
+		return &K8sResponse{Type: ResponseTypeNeedsClarification, Summary: clarification.Question, Clarification: clarification}, nil
 	}
-
-	// Initialize workloads sub-agent if needed
-	if a.workloads == nil {
-		a.workloads = workloads.NewSubAgent(&clientAdapter{client: a.client}, a.debug)
+	if kubeContext != opts.Context {
+		opts.Context = kubeContext
+		a = a.ensureClient(opts)
 	}
 
-	// Initialize networking sub-agent if needed
-	if a.networking == nil {
-		a.networking = networking.NewSubAgent(&networkingClientAdapter{client: a.client}, a.debug)
+	response, err := a.handleQuery(ctx, query, opts)
+	if err == nil && response != nil && response.Plan != nil {
+		plan := response.Plan
+		if plan.CreatedAt.IsZero() {
+			plan.CreatedAt = time.Now().UTC()
+		}
+		if plan.Question == "" || plan.Question == plan.Summary {
+			plan.Question = query
+		}
+		a.previewPolicy(plan, opts.Namespace)
+		a.observePlan(ctx, plan, opts.Namespace)
 	}
+	return response, err
+}
 
-	// Initialize storage sub-agent if needed
-	if a.storage == nil {
-		a.storage = storage.NewSubAgent(&storageClientAdapter{client: a.client}, a.debug)
-	}
+// handleQuery answers a query with the client for its context
+func (a *Agent) handleQuery(ctx context.Context, query string, opts QueryOptions) (*K8sResponse, error) {
+	// Analyze the query, and narrow the options to the namespace, names
+	// and labels it mentions
+	analysis := a.analyzeQuery(query)
+	a.refineIntent(ctx, query, &analysis)
+	entities := a.extractEntities(ctx, query)
+	analysis.NamespaceHint = entities.Namespace
+	opts = opts.withEntities(entities)
 
-	// Initialize helm sub-agent if needed
-	if a.helm == nil {
-		a.helm = helm.NewSubAgent(&helmClientAdapter{client: a.client}, a.debug)
-	}
+	logger.Debugf("analysis: readonly=%v, category=%s (%.2f by %s), resources=%v, namespace=%s", analysis.IsReadOnly, analysis.Category, analysis.Confidence, analysis.ClassifiedBy, analysis.Resources, opts.Namespace)
 
-	// Initialize sre sub-agent if needed
-	if a.sre == nil {
-		a.sre = sre.NewSubAgent(&sreClientAdapter{client: a.client}, a.debug)
+	// Delegate permission queries to the rbac sub-agent
+	if analysis.Category == "rbac" {
+		return a.handleRBACQuery(ctx, query, analysis, opts)
 	}
 
-	// Initialize telemetry sub-agent if needed
-	if a.telemetry == nil {
-		a.telemetry = telemetry.NewSubAgent(&telemetryClientAdapter{client: a.client}, a.debug)
+	// Delegate Route, DeploymentConfig, project and SCC queries to the
+	// openshift sub-agent
+	if analysis.Category == "openshift" {
+		return a.handleOpenShiftQuery(ctx, query, analysis, opts)
 	}
 
-	// Analyze the query
-	analysis := a.analyzeQuery(query)
-
-	if a.debug {
-		fmt.Printf("[k8s-agent] analysis: readonly=%v, category=%s, resources=%v\n",
-			analysis.IsReadOnly, analysis.Category, analysis.Resources)
+	// Delegate Velero backup, restore and install queries to the backup
+	// sub-agent
+	if analysis.Category == "backup" {
+		return a.handleBackupQuery(ctx, query, analysis, opts)
 	}
 
 	// Delegate workload queries to the workloads sub-agent
@@ -329,6 +538,8 @@ func (a *Agent) HandleQuery(ctx context.Context, query string, opts QueryOptions
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate plan: %w", err)
 	}
+	a.previewManifestChecks(ctx, plan)
+	a.previewImageScans(ctx, plan)
 
 	return &K8sResponse{
 		Type:          ResponseTypePlan,
@@ -340,36 +551,57 @@ func (a *Agent) HandleQuery(ctx context.Context, query string, opts QueryOptions
 
 // ApplyPlan executes an approved plan
 func (a *Agent) ApplyPlan(ctx context.Context, plan *K8sPlan, opts ApplyOptions) error {
-	if a.debug {
-		fmt.Printf("[k8s-agent] applying plan: %s\n", plan.Summary)
+	logger.Debugf("applying plan: %s", plan.Summary)
+	if dryrun.Enabled() {
+		opts.DryRun = true
+	}
+
+	// Check the plan against the operator policy first, then make sure the
+	// objects it changes still look the way they did when it was generated
+	if !opts.DryRun {
+		if err := a.enforcePolicy(plan, opts); err != nil {
+			return err
+		}
+		if err := a.revalidatePlan(ctx, plan, opts); err != nil {
+			return err
+		}
+	}
+
+	// Validate manifests before anything changes, so a rejected manifest
+	// does not leave the plan half applied
+	if len(plan.Manifests) > 0 && !opts.DryRun {
+		checks := CheckPlanManifests(ctx, a.client, plan, opts.Schema)
+		plan.ManifestChecks = checks
+		if failed := FailedChecks(checks); len(failed) > 0 {
+			if !opts.Force {
+				return manifestChecksError(failed)
+			}
+			for _, check := range failed {
+				fmt.Printf("[k8s-agent] warning: applying despite failed validation: %s\n", check)
+			}
+		}
 	}
 
 	// Execute infrastructure commands first
 	for _, cmd := range plan.Infrastructure {
-		if a.debug {
-			fmt.Printf("[k8s-agent] infra: %s %s\n", cmd.Service, cmd.Operation)
-		}
+		logger.Debugf("infra: %s %s", cmd.Service, cmd.Operation)
 		// Infrastructure commands will be handled by the AWS client
 		// This is a placeholder for the actual implementation
 	}
 
 	// Execute bootstrap commands
 	for _, cmd := range plan.Bootstrap {
-		if a.debug {
-			fmt.Printf("[k8s-agent] bootstrap: %s %s\n", cmd.Type, cmd.Operation)
-		}
+		logger.Debugf("bootstrap: %s %s", cmd.Type, cmd.Operation)
 		// Bootstrap commands require SSH or specific tools
 		// This is a placeholder for the actual implementation
 	}
 
 	// Execute kubectl commands
 	for _, cmd := range plan.KubectlCmds {
-		if a.debug {
-			fmt.Printf("[k8s-agent] kubectl: %v\n", cmd.Args)
-		}
+		logger.Debugf("kubectl: %v", cmd.Args)
 
 		if opts.DryRun {
-			fmt.Printf("[dry-run] kubectl %s\n", strings.Join(cmd.Args, " "))
+			dryrun.Print(os.Stdout, "kubectl", cmd.Args)
 			continue
 		}
 
@@ -378,9 +610,7 @@ func (a *Agent) ApplyPlan(ctx context.Context, plan *K8sPlan, opts ApplyOptions)
 			return fmt.Errorf("kubectl command failed: %w", err)
 		}
 
-		if a.debug {
-			fmt.Printf("[k8s-agent] output: %s\n", output)
-		}
+		logger.Debugf("output: %s", output)
 
 		// Handle wait conditions
 		if cmd.WaitFor != nil {
@@ -393,21 +623,17 @@ func (a *Agent) ApplyPlan(ctx context.Context, plan *K8sPlan, opts ApplyOptions)
 
 	// Execute helm commands
 	for _, cmd := range plan.HelmCmds {
-		if a.debug {
-			fmt.Printf("[k8s-agent] helm: %s %s\n", cmd.Action, cmd.Release)
-		}
+		logger.Debugf("helm: %s %s", cmd.Action, cmd.Release)
 		// Helm commands will be handled by the helm sub-agent
 		// This is a placeholder for the actual implementation
 	}
 
 	// Apply manifests
 	for _, manifest := range plan.Manifests {
-		if a.debug {
-			fmt.Printf("[k8s-agent] applying manifest: %s/%s\n", manifest.Kind, manifest.Name)
-		}
+		logger.Debugf("applying manifest: %s/%s", manifest.Kind, manifest.Name)
 
 		if opts.DryRun {
-			fmt.Printf("[dry-run] apply %s/%s\n", manifest.Kind, manifest.Name)
+			dryrun.PrintInput(os.Stdout, "kubectl", []string{"apply", "-f", "-"}, manifest.Kind+"/"+manifest.Name)
 			continue
 		}
 
@@ -419,9 +645,7 @@ func (a *Agent) ApplyPlan(ctx context.Context, plan *K8sPlan, opts ApplyOptions)
 
 	// Run validations
 	for _, validation := range plan.Validations {
-		if a.debug {
-			fmt.Printf("[k8s-agent] validating: %s\n", validation.Name)
-		}
+		logger.Debugf("validating: %s", validation.Name)
 		// Validation logic will be implemented
 	}
 
@@ -464,14 +688,10 @@ func (a *Agent) analyzeQuery(query string) QueryAnalysis {
 	}
 
 	// Determine category
-	analysis.Category = a.categorizeQuery(queryLower, analysis)
-
-	// Check for namespace hints
-	if strings.Contains(queryLower, "kube-system") {
-		analysis.NamespaceHint = "kube-system"
-	} else if strings.Contains(queryLower, "default namespace") {
-		analysis.NamespaceHint = "default"
-	}
+	analysis.Candidates = classifyIntent(queryLower)
+	analysis.Category, analysis.Confidence = analysis.Candidates[0].Category, analysis.Candidates[0].Confidence
+	analysis.ClassifiedBy = "rules"
+	analysis.applyCategory()
 
 	// Check for cluster scope
 	analysis.ClusterScope = strings.Contains(queryLower, "cluster") ||
@@ -481,56 +701,6 @@ func (a *Agent) analyzeQuery(query string) QueryAnalysis {
 	return analysis
 }
 
-// categorizeQuery determines the category of the query
-func (a *Agent) categorizeQuery(query string, analysis QueryAnalysis) string {
-	// Cluster operations
-	if strings.Contains(query, "cluster") && (strings.Contains(query, "create") ||
-		strings.Contains(query, "provision") || strings.Contains(query, "setup")) {
-		return "cluster_provisioning"
-	}
-
-	if strings.Contains(query, "node") && (strings.Contains(query, "add") ||
-		strings.Contains(query, "remove") || strings.Contains(query, "scale")) {
-		return "cluster_scaling"
-	}
-
-	// Workload operations
-	if containsAny(query, []string{"deploy", "deployment", "pod", "replica", "statefulset", "daemonset"}) {
-		return "workloads"
-	}
-
-	// Networking
-	if containsAny(query, []string{"service", "ingress", "loadbalancer", "network", "endpoint"}) {
-		return "networking"
-	}
-
-	// Storage
-	if containsAny(query, []string{"pv", "pvc", "storage", "volume", "configmap", "secret"}) {
-		return "storage"
-	}
-
-	// Helm
-	if containsAny(query, []string{"helm", "chart", "release"}) {
-		return "helm"
-	}
-
-	// Telemetry and metrics
-	if containsAny(query, []string{"metrics", "usage", "top", "cpu", "memory", "resource usage",
-		"utilization", "stats", "statistics", "consumption", "allocat"}) {
-		return "telemetry"
-	}
-
-	// SRE and Troubleshooting
-	if containsAny(query, []string{"health", "healthy", "diagnose", "diagnostic", "troubleshoot",
-		"error", "issue", "problem", "crash", "failing", "failed",
-		"why is", "why are", "what is wrong", "what's wrong",
-		"fix", "remediate", "analyze", "investigate"}) {
-		return "sre"
-	}
-
-	return "general"
-}
-
 // executeReadOnly handles read only K8s operations
 func (a *Agent) executeReadOnly(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*K8sResponse, error) {
 	var result strings.Builder
@@ -658,6 +828,17 @@ func (a *Agent) executeReadOnly(ctx context.Context, query string, analysis Quer
 		result.WriteString("Cluster Health:\n")
 		result.WriteString(fmt.Sprintf("  Healthy: %v\n", health.Healthy))
 		result.WriteString(fmt.Sprintf("  Message: %s\n", health.Message))
+		if len(health.Components) > 0 {
+			result.WriteString("  Components:\n")
+			names := make([]string, 0, len(health.Components))
+			for name := range health.Components {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				result.WriteString(fmt.Sprintf("    %s: %s\n", name, health.Components[name]))
+			}
+		}
 		if len(health.NodeStatus) > 0 {
 			result.WriteString("  Nodes:\n")
 			for name, status := range health.NodeStatus {
@@ -682,15 +863,16 @@ func (a *Agent) executeReadOnly(ctx context.Context, query string, analysis Quer
 
 // handleWorkloadQuery delegates workload queries to the workloads sub-agent
 func (a *Agent) handleWorkloadQuery(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*K8sResponse, error) {
-	if a.debug {
-		fmt.Printf("[k8s-agent] delegating to workloads sub-agent\n")
-	}
+	logger.Debug("delegating to workloads sub-agent")
 
 	// Convert QueryOptions to workloads.QueryOptions
-	workloadOpts := workloads.QueryOptions{
-		Namespace:     opts.Namespace,
-		AllNamespaces: analysis.ClusterScope,
-	}
+	// Qualifiers like "tagged app=web" or "pending pods on node x" become
+	// label/field selectors
+	qf := qfilter.Compile(query)
+	workloadOpts := opts.workloadOptions()
+	workloadOpts.AllNamespaces = analysis.ClusterScope
+	workloadOpts.LabelSelector = joinSelectors(workloadOpts.LabelSelector, qf.KubernetesLabelSelector("pods"))
+	workloadOpts.FieldSelector = joinSelectors(workloadOpts.FieldSelector, qf.KubernetesFieldSelector("pods"))
 
 	response, err := a.workloads.HandleQuery(ctx, query, workloadOpts)
 	if err != nil {
@@ -718,6 +900,12 @@ func (a *Agent) handleWorkloadQuery(ctx context.Context, query string, analysis
 		if response.Plan != nil {
 			k8sResponse.Plan = convertWorkloadPlanToK8sPlan(response.Plan)
 		}
+	case workloads.ResponseTypeNeedsClarification:
+		k8sResponse.Type = ResponseTypeNeedsClarification
+		k8sResponse.Summary = response.Message
+		if c := response.Clarification; c != nil {
+			k8sResponse.Clarification = &Clarification{Question: c.Question, Field: ClarifyResource, Options: c.Options}
+		}
 	}
 
 	return k8sResponse, nil
@@ -756,14 +944,13 @@ func convertWorkloadPlanToK8sPlan(wp *workloads.WorkloadPlan) *K8sPlan {
 
 // handleNetworkingQuery delegates networking queries to the networking sub-agent
 func (a *Agent) handleNetworkingQuery(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*K8sResponse, error) {
-	if a.debug {
-		fmt.Printf("[k8s-agent] delegating to networking sub-agent\n")
-	}
+	logger.Debug("delegating to networking sub-agent")
 
 	// Convert QueryOptions to networking.QueryOptions
 	networkingOpts := networking.QueryOptions{
 		Namespace:     opts.Namespace,
 		AllNamespaces: analysis.ClusterScope,
+		LabelSelector: labelSelectorFor(query, "services", opts),
 	}
 
 	response, err := a.networking.HandleQuery(ctx, query, networkingOpts)
@@ -810,9 +997,10 @@ func convertNetworkingPlanToK8sPlan(np *networking.NetworkingPlan) *K8sPlan {
 		Bindings: make(map[string]string),
 	}
 
-	// Convert steps to kubectl commands or manifests
+	// Convert steps to kubectl commands or manifests; a step carrying a
+	// manifest is its kubectl apply -f -
 	for _, step := range np.Steps {
-		if step.Command == "kubectl" {
+		if step.Command == "kubectl" && step.Manifest == "" {
 			cmd := KubectlCmd{
 				Args:   step.Args,
 				Reason: step.Reason,
@@ -842,14 +1030,13 @@ func convertNetworkingPlanToK8sPlan(np *networking.NetworkingPlan) *K8sPlan {
 
 // handleStorageQuery delegates storage queries to the storage sub-agent
 func (a *Agent) handleStorageQuery(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*K8sResponse, error) {
-	if a.debug {
-		fmt.Printf("[k8s-agent] delegating to storage sub-agent\n")
-	}
+	logger.Debug("delegating to storage sub-agent")
 
 	// Convert QueryOptions to storage.QueryOptions
 	storageOpts := storage.QueryOptions{
 		Namespace:     opts.Namespace,
 		AllNamespaces: analysis.ClusterScope,
+		LabelSelector: labelSelectorFor(query, "persistentvolumeclaims", opts),
 	}
 
 	response, err := a.storage.HandleQuery(ctx, query, storageOpts)
@@ -895,10 +1082,23 @@ func convertStoragePlanToK8sPlan(sp *storage.StoragePlan) *K8sPlan {
 		Notes:    sp.Notes,
 		Bindings: make(map[string]string),
 	}
+	appendStorageSteps(plan, sp.Steps)
+	return plan
+}
 
-	// Convert steps to kubectl commands or manifests
-	for _, step := range sp.Steps {
-		if step.Command == "kubectl" {
+// appendStorageSteps adds storage plan steps to a K8s plan as kubectl
+// commands or manifests; a step carrying a manifest is its kubectl apply
+// -f -. Cloud CLI steps, such as disk snapshots, become bootstrap commands.
+func appendStorageSteps(plan *K8sPlan, steps []storage.StorageStep) {
+	for _, step := range steps {
+		switch {
+		case step.Manifest != "":
+			plan.Manifests = append(plan.Manifests, Manifest{
+				Name:    step.ID,
+				Content: step.Manifest,
+				Reason:  step.Reason,
+			})
+		case step.Command == "kubectl":
 			cmd := KubectlCmd{
 				Args:   step.Args,
 				Reason: step.Reason,
@@ -911,26 +1111,22 @@ func convertStoragePlanToK8sPlan(sp *storage.StoragePlan) *K8sPlan {
 				}
 			}
 			plan.KubectlCmds = append(plan.KubectlCmds, cmd)
-		}
-
-		// Handle manifest-based steps
-		if step.Manifest != "" {
-			plan.Manifests = append(plan.Manifests, Manifest{
-				Name:    step.ID,
-				Content: step.Manifest,
-				Reason:  step.Reason,
+		default:
+			plan.Bootstrap = append(plan.Bootstrap, BootstrapCommand{
+				Type:      step.Command,
+				Operation: step.ID,
+				Target:    plan.ClusterName,
+				Command:   strings.TrimSpace(step.Command + " " + strings.Join(step.Args, " ")),
+				Reason:    step.Description,
+				Produces:  step.Produces,
 			})
 		}
 	}
-
-	return plan
 }
 
 // handleHelmQuery delegates helm queries to the helm sub-agent
 func (a *Agent) handleHelmQuery(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*K8sResponse, error) {
-	if a.debug {
-		fmt.Printf("[k8s-agent] delegating to helm sub-agent\n")
-	}
+	logger.Debug("delegating to helm sub-agent")
 
 	// Convert QueryOptions to helm.QueryOptions
 	helmOpts := helm.QueryOptions{
@@ -956,6 +1152,9 @@ func (a *Agent) handleHelmQuery(ctx context.Context, query string, analysis Quer
 		} else if response.Data != nil {
 			// Format structured data as readable output
 			k8sResponse.Result = formatHelmData(response.Data)
+			if response.Message != "" {
+				k8sResponse.Result += "\n" + response.Message
+			}
 		} else {
 			k8sResponse.Result = response.Message
 		}
@@ -965,20 +1164,21 @@ func (a *Agent) handleHelmQuery(ctx context.Context, query string, analysis Quer
 		k8sResponse.Summary = response.Message
 		// Convert helm.HelmPlan to K8sPlan
 		if response.Plan != nil {
-			k8sResponse.Plan = convertHelmPlanToK8sPlan(response.Plan)
+			k8sResponse.Plan = HelmPlanToK8sPlan(response.Plan)
 		}
 	}
 
 	return k8sResponse, nil
 }
 
-// convertHelmPlanToK8sPlan converts a helm plan to a K8s plan
-func convertHelmPlanToK8sPlan(hp *helm.HelmPlan) *K8sPlan {
+// HelmPlanToK8sPlan converts a helm plan to a K8s plan
+func HelmPlanToK8sPlan(hp *helm.HelmPlan) *K8sPlan {
 	plan := &K8sPlan{
 		Version:  hp.Version,
 		Question: hp.Summary,
 		Summary:  hp.Summary,
 		Notes:    hp.Notes,
+		Warnings: hp.Warnings,
 		Bindings: make(map[string]string),
 	}
 
@@ -1044,9 +1244,7 @@ func extractHelmArgsInfo(args []string) (action, release, chart, namespace strin
 
 // handleSREQuery delegates SRE queries to the sre sub-agent
 func (a *Agent) handleSREQuery(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*K8sResponse, error) {
-	if a.debug {
-		fmt.Printf("[k8s-agent] delegating to sre sub-agent\n")
-	}
+	logger.Debug("delegating to sre sub-agent")
 
 	// Convert QueryOptions to sre.QueryOptions
 	sreOpts := sre.QueryOptions{
@@ -1078,6 +1276,7 @@ func (a *Agent) handleSREQuery(ctx context.Context, query string, analysis Query
 		// Include the diagnostic report as additional data
 		if response.Report != nil {
 			k8sResponse.Result = formatDiagnosticReport(response.Report)
+			k8sResponse.Report = response.Report
 		}
 	case sre.ResponseTypePlan:
 		k8sResponse.Type = ResponseTypePlan
@@ -1118,9 +1317,7 @@ func convertSREPlanToK8sPlan(sp *sre.SREPlan) *K8sPlan {
 
 // handleTelemetryQuery delegates telemetry queries to the telemetry sub-agent
 func (a *Agent) handleTelemetryQuery(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*K8sResponse, error) {
-	if a.debug {
-		fmt.Printf("[k8s-agent] delegating to telemetry sub-agent\n")
-	}
+	logger.Debug("delegating to telemetry sub-agent")
 
 	// Convert QueryOptions to telemetry.QueryOptions
 	telemetryOpts := telemetry.QueryOptions{
@@ -1160,6 +1357,169 @@ func (a *Agent) handleTelemetryQuery(ctx context.Context, query string, analysis
 	return k8sResponse, nil
 }
 
+// handleRBACQuery delegates permission queries to the rbac sub-agent
+func (a *Agent) handleRBACQuery(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*K8sResponse, error) {
+	logger.Debug("delegating to rbac sub-agent")
+
+	response, err := a.rbac.HandleQuery(ctx, query, rbac.QueryOptions{Namespace: opts.Namespace})
+	if err != nil {
+		return nil, err
+	}
+
+	k8sResponse := &K8sResponse{
+		NeedsApproval: false,
+	}
+
+	switch response.Type {
+	case rbac.ResponseTypeResult:
+		k8sResponse.Type = ResponseTypeResult
+		if str, ok := response.Data.(string); ok {
+			k8sResponse.Result = str
+		} else {
+			k8sResponse.Result = response.Message
+		}
+	case rbac.ResponseTypeError:
+		k8sResponse.Type = ResponseTypeError
+		k8sResponse.Result = response.Message
+		if response.Error != nil {
+			k8sResponse.Error = response.Error
+		}
+	}
+
+	return k8sResponse, nil
+}
+
+// handleOpenShiftQuery delegates OpenShift resource queries to the openshift
+// sub-agent, switching the client to oc when the cluster is OpenShift
+func (a *Agent) handleOpenShiftQuery(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*K8sResponse, error) {
+	logger.Debug("delegating to openshift sub-agent")
+
+	if a.GetCloudProvider() != CloudProviderOpenShift {
+		info, err := a.client.DetectOpenShift(ctx)
+		if err == nil && !info.IsOpenShift() {
+			return &K8sResponse{
+				Type:   ResponseTypeResult,
+				Result: "This cluster does not serve the OpenShift APIs (route.openshift.io, project.openshift.io, security.openshift.io). Routes, DeploymentConfigs, projects and SCCs are only available on OpenShift and OKD.",
+			}, nil
+		}
+		if err == nil {
+			a.SetCloudProvider(CloudProviderOpenShift)
+			a.client.UseOcIfAvailable()
+		}
+	}
+
+	response, err := a.openshift.HandleQuery(ctx, query, openshift.QueryOptions{Namespace: opts.Namespace})
+	if err != nil {
+		return nil, err
+	}
+
+	k8sResponse := &K8sResponse{
+		NeedsApproval: false,
+	}
+
+	switch response.Type {
+	case openshift.ResponseTypeResult:
+		k8sResponse.Type = ResponseTypeResult
+		if str, ok := response.Data.(string); ok {
+			k8sResponse.Result = str
+		} else {
+			k8sResponse.Result = response.Message
+		}
+	case openshift.ResponseTypeError:
+		k8sResponse.Type = ResponseTypeError
+		k8sResponse.Result = response.Message
+		if response.Error != nil {
+			k8sResponse.Error = response.Error
+		}
+	}
+
+	return k8sResponse, nil
+}
+
+// handleBackupQuery delegates Velero queries to the backup sub-agent
+func (a *Agent) handleBackupQuery(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*K8sResponse, error) {
+	logger.Debug("delegating to backup sub-agent")
+
+	provider := a.GetCloudProvider()
+	if provider == CloudProviderOpenShift {
+		provider = CloudProviderUnknown
+	}
+	response, err := a.backup.HandleQuery(ctx, query, backup.QueryOptions{
+		Namespace: opts.Namespace,
+		Provider:  string(provider),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	k8sResponse := &K8sResponse{
+		NeedsApproval: false,
+	}
+
+	switch response.Type {
+	case backup.ResponseTypeResult:
+		k8sResponse.Type = ResponseTypeResult
+		if str, ok := response.Data.(string); ok {
+			k8sResponse.Result = str
+		} else {
+			k8sResponse.Result = response.Message
+		}
+	case backup.ResponseTypePlan:
+		k8sResponse.Type = ResponseTypePlan
+		k8sResponse.NeedsApproval = true
+		k8sResponse.Summary = response.Message
+		if response.Plan != nil {
+			k8sResponse.Plan = convertBackupPlanToK8sPlan(response.Plan)
+		}
+	case backup.ResponseTypeError:
+		k8sResponse.Type = ResponseTypeError
+		k8sResponse.Result = response.Message
+		if response.Error != nil {
+			k8sResponse.Error = response.Error
+		}
+	}
+
+	return k8sResponse, nil
+}
+
+// convertBackupPlanToK8sPlan converts a backup plan to a K8s plan: helm
+// steps install Velero, and Backup and Restore objects are manifests
+func convertBackupPlanToK8sPlan(bp *backup.BackupPlan) *K8sPlan {
+	plan := &K8sPlan{
+		Version:  bp.Version,
+		Question: bp.Summary,
+		Summary:  bp.Summary,
+		Notes:    bp.Notes,
+		Warnings: bp.Warnings,
+		Bindings: make(map[string]string),
+	}
+
+	for _, step := range bp.Steps {
+		switch {
+		case step.Command == "helm":
+			action, release, chart, namespace := extractHelmArgsInfo(step.Args)
+			plan.HelmCmds = append(plan.HelmCmds, HelmCmd{
+				Action:    action,
+				Release:   release,
+				Chart:     chart,
+				Namespace: namespace,
+				Args:      step.Args,
+				Reason:    step.Description,
+			})
+		case step.Manifest != "":
+			plan.Manifests = append(plan.Manifests, Manifest{
+				APIVersion: "velero.io/v1",
+				Name:       step.ID,
+				Namespace:  backup.VeleroNamespace,
+				Content:    step.Manifest,
+				Reason:     step.Description,
+			})
+		}
+	}
+
+	return plan
+}
+
 // formatTelemetryData formats telemetry data for display
 func formatTelemetryData(data interface{}, message string) string {
 	var sb strings.Builder
@@ -1229,6 +1589,8 @@ func formatTelemetryData(data interface{}, message string) string {
 				sb.WriteString(fmt.Sprintf("  %s: CPU %s, Memory %s\n", c.Name, c.CPUUsage, c.MemUsage))
 			}
 		}
+	case *telemetry.CapacityReport:
+		telemetry.WriteCapacityTable(&sb, v)
 	case []telemetry.ContainerMetrics:
 		if len(v) == 0 {
 			return "No container metrics available"
@@ -1284,13 +1646,41 @@ func formatDiagnosticReport(report *sre.DiagnosticReport) string {
 		sb.WriteString("No issues found.\n")
 	}
 
-	if len(report.Events) > 0 {
+	if len(report.RootCauses) > 0 {
+		sb.WriteString("\nRoot Cause:\n")
+		for _, rc := range report.RootCauses {
+			for _, line := range strings.Split(strings.TrimRight(sre.FormatRootCause(rc), "\n"), "\n") {
+				sb.WriteString("  " + line + "\n")
+			}
+		}
+	}
+
+	if len(report.Timeline) > 0 {
+		sb.WriteString("\nEvent Timeline:\n")
+		for _, group := range report.Timeline {
+			sb.WriteString(fmt.Sprintf("  %s\n", sre.FormatTimelineEntry(group)))
+		}
+	} else if len(report.Events) > 0 {
 		sb.WriteString("\nRecent Events:\n")
 		for _, event := range report.Events {
 			sb.WriteString(fmt.Sprintf("  [%s] %s: %s\n", event.Type, event.Reason, event.Message))
 		}
 	}
 
+	if len(report.TrackedErrors) > 0 {
+		sb.WriteString("\nRelated Errors:\n")
+		for _, e := range report.TrackedErrors {
+			sb.WriteString(fmt.Sprintf("  [%s] %s: %s", e.Source, e.ID, e.Title))
+			if e.Events != "" {
+				sb.WriteString(fmt.Sprintf(" (%s events, %d users)", e.Events, e.Users))
+			}
+			sb.WriteString("\n")
+			if e.Link != "" {
+				sb.WriteString(fmt.Sprintf("     %s\n", e.Link))
+			}
+		}
+	}
+
 	if len(report.Remediation) > 0 {
 		sb.WriteString("\nRemediation Steps:\n")
 		for _, step := range report.Remediation {
@@ -1309,6 +1699,8 @@ func formatNetworkingData(data interface{}) string {
 	var sb strings.Builder
 
 	switch v := data.(type) {
+	case *networking.ReachabilityReport:
+		return v.String()
 	case []networking.ServiceInfo:
 		if len(v) == 0 {
 			return "No services found"
@@ -1393,6 +1785,39 @@ func formatStorageData(data interface{}) string {
 	var sb strings.Builder
 
 	switch v := data.(type) {
+	case []*storage.PVCDiagnosis:
+		if len(v) == 0 {
+			return "No pending PersistentVolumeClaims found"
+		}
+		for _, d := range v {
+			sb.WriteString(d.String())
+		}
+	case []storage.VolumeSnapshotInfo:
+		if len(v) == 0 {
+			return "No volume snapshots found"
+		}
+		sb.WriteString("Volume Snapshots:\n")
+		sb.WriteString(fmt.Sprintf("%-40s %-25s %-10s %-12s %-25s %s\n", "NAME", "PVC", "READY", "SIZE", "CLASS", "AGE"))
+		for _, snap := range v {
+			sb.WriteString(fmt.Sprintf("%-40s %-25s %-10t %-12s %-25s %s\n",
+				snap.Name, snap.PVC, snap.ReadyToUse, snap.RestoreSize, snap.Class, snap.Age))
+			if snap.ErrorMessage != "" {
+				sb.WriteString(fmt.Sprintf("  error: %s\n", snap.ErrorMessage))
+			}
+		}
+	case []storage.VolumeSnapshotClassInfo:
+		if len(v) == 0 {
+			return "No volume snapshot classes found"
+		}
+		sb.WriteString("Volume Snapshot Classes:\n")
+		sb.WriteString(fmt.Sprintf("%-30s %-40s %-15s %s\n", "NAME", "DRIVER", "DELETION", "DEFAULT"))
+		for _, c := range v {
+			isDefault := ""
+			if c.IsDefault {
+				isDefault = "(default)"
+			}
+			sb.WriteString(fmt.Sprintf("%-30s %-40s %-15s %s\n", c.Name, c.Driver, c.DeletionPolicy, isDefault))
+		}
 	case []storage.PVInfo:
 		if len(v) == 0 {
 			return "No persistent volumes found"
@@ -1534,6 +1959,12 @@ func formatHelmData(data interface{}) string {
 			sb.WriteString(fmt.Sprintf("%-30s %-15s %-15s %s\n",
 				chart.Name, chart.Version, chart.AppVersion, desc))
 		}
+	case []helm.ChartSearchResult:
+		if len(v) == 0 {
+			return "No charts found"
+		}
+		sb.WriteString("Helm Charts (best match first):\n")
+		sb.WriteString(helm.FormatChartSearchResults(v))
 	case []helm.ReleaseHistoryEntry:
 		if len(v) == 0 {
 			return "No history found"
@@ -1566,6 +1997,21 @@ func (a *Agent) generatePlan(ctx context.Context, query string, analysis QueryAn
 		Bindings:    make(map[string]string),
 	}
 
+	// Upgrades, node patching and add-ons follow fixed steps that the
+	// providers implement
+	if analysis.Category == "cluster_upgrade" {
+		return a.generateUpgradePlan(ctx, query, opts, plan)
+	}
+	if analysis.Category == "node_patching" {
+		return a.generatePatchPlan(query, opts, plan)
+	}
+	if analysis.Category == "cluster_addon" {
+		return a.generateAddonPlan(query, opts, plan)
+	}
+	if analysis.Category == "workload_identity" {
+		return a.generateWorkloadIdentityPlan(query, opts, plan)
+	}
+
 	// If AI decision function is available, use it to generate the plan
 	if a.aiDecisionFn != nil {
 		return a.generatePlanWithAI(ctx, query, analysis, opts, plan)
@@ -1592,8 +2038,21 @@ Return a JSON object with the following structure:
   "kubectl_cmds": [
     {"args": ["kubectl", "arg1", "arg2"], "namespace": "default", "reason": "why this command"}
   ],
-  "manifests": [
-    {"kind": "Deployment", "api_version": "apps/v1", "name": "name", "namespace": "default", "content": "yaml content", "reason": "why"}
+  "apps": [
+    {
+      "reason": "why this application is deployed",
+      "spec": {
+        "kind": "Deployment", "name": "dns-1035-name", "namespace": "default", "image": "repo/image:tag", "replicas": 2, "port": 8080,
+        "env": [{"name": "KEY", "value": "value"}],
+        "resources": {"cpu_request": "250m", "cpu_limit": "1", "memory_request": "256Mi", "memory_limit": "512Mi"},
+        "probe": {"path": "/healthz", "readiness": true, "liveness": true},
+        "service_type": "ClusterIP",
+        "ingress": {"host": "app.example.com", "path": "/", "class_name": "nginx", "tls_secret": "app-tls"},
+        "autoscale": {"min_replicas": 2, "max_replicas": 6, "cpu_percent": 80},
+        "pdb": {"min_available": 1},
+        "network_policy": {"allow_namespaces": ["ingress-nginx"]}
+      }
+    }
   ],
   "validations": [
     {"name": "check name", "command": "kubectl get ...", "expected": "expected output", "fail_action": "fail"}
@@ -1602,6 +2061,13 @@ Return a JSON object with the following structure:
   "warnings": ["any warnings"]
 }
 
+Describe applications to deploy as "apps" specs; do not write YAML. Manifests
+(Deployment, Service, Ingress, HPA, PDB, NetworkPolicy) are generated from
+each spec. Omit spec fields that the request does not need. Set "kind" to
+"StatefulSet" with "volume": {"size": "20Gi"} for persistent storage, "Job"
+for work that runs once, or "CronJob" with "schedule": "0 2 * * *" for
+scheduled work; "command": ["/bin/sh", "-c", "..."] replaces the entrypoint.
+
 Only include the sections that are relevant. Return valid JSON only.`,
 		query,
 		opts.ClusterType,
@@ -1617,16 +2083,29 @@ Only include the sections that are relevant. Return valid JSON only.`,
 	// Parse the AI response into the plan
 	if err := a.parsePlanResponse(response, plan); err != nil {
 		// Fall back to basic plan generation
-		if a.debug {
-			fmt.Printf("[k8s-agent] AI plan parse failed, using basic plan: %v\n", err)
-		}
+		logger.Debugf("AI plan parse failed, using basic plan: %v", err)
 		return a.generateBasicPlan(query, analysis, opts, plan)
 	}
 
 	return plan, nil
 }
 
-// parsePlanResponse parses AI generated plan JSON
+// aiPlanResponse is the JSON generatePlanWithAI asks the model for
+type aiPlanResponse struct {
+	Summary     string       `json:"summary"`
+	KubectlCmds []KubectlCmd `json:"kubectl_cmds"`
+	Apps        []struct {
+		Reason string              `json:"reason"`
+		Spec   manifestgen.AppSpec `json:"spec"`
+	} `json:"apps"`
+	Validations []Validation `json:"validations"`
+	Notes       []string     `json:"notes"`
+	Warnings    []string     `json:"warnings"`
+}
+
+// parsePlanResponse parses AI generated plan JSON. Applications come back as
+// typed specs and are rendered by manifestgen, so the plan never carries
+// model-written YAML; a spec that fails validation is dropped with a warning.
 func (a *Agent) parsePlanResponse(response string, plan *K8sPlan) error {
 	// Find JSON in response
 	start := strings.Index(response, "{")
@@ -1635,22 +2114,34 @@ func (a *Agent) parsePlanResponse(response string, plan *K8sPlan) error {
 		return fmt.Errorf("no valid JSON found in response")
 	}
 
-	// This would use json.Unmarshal in a real implementation
-	// For now, we extract the summary at minimum
-	jsonStr := response[start : end+1]
-	if strings.Contains(jsonStr, "summary") {
-		// Extract summary
-		summaryStart := strings.Index(jsonStr, `"summary"`)
-		if summaryStart != -1 {
-			valueStart := strings.Index(jsonStr[summaryStart:], `:`) + summaryStart + 1
-			valueEnd := strings.Index(jsonStr[valueStart:], `"`)
-			if valueEnd != -1 {
-				quoteStart := valueStart + valueEnd + 1
-				quoteEnd := strings.Index(jsonStr[quoteStart:], `"`)
-				if quoteEnd != -1 {
-					plan.Summary = jsonStr[quoteStart : quoteStart+quoteEnd]
-				}
-			}
+	var parsed aiPlanResponse
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return fmt.Errorf("invalid plan JSON: %w", err)
+	}
+
+	if parsed.Summary != "" {
+		plan.Summary = parsed.Summary
+	}
+	plan.KubectlCmds = append(plan.KubectlCmds, parsed.KubectlCmds...)
+	plan.Validations = append(plan.Validations, parsed.Validations...)
+	plan.Notes = append(plan.Notes, parsed.Notes...)
+	plan.Warnings = append(plan.Warnings, parsed.Warnings...)
+
+	for _, app := range parsed.Apps {
+		manifests, err := manifestgen.Build(app.Spec)
+		if err != nil {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("skipped application %q: %v", app.Spec.Name, err))
+			continue
+		}
+		for _, m := range manifests {
+			plan.Manifests = append(plan.Manifests, Manifest{
+				Kind:       m.Kind,
+				APIVersion: m.APIVersion,
+				Name:       m.Name,
+				Namespace:  m.Namespace,
+				Content:    m.Content,
+				Reason:     app.Reason,
+			})
 		}
 	}
 
@@ -1957,16 +2448,15 @@ func (a *Agent) CheckAKSHealth(ctx context.Context, clusterName string) (*Health
 	return provider.Health(ctx, clusterName)
 }
 
+// clusterResourceFetchLimit caps the kubectl calls GetClusterResources
+// runs at once
+const clusterResourceFetchLimit = 4
+
 // GetClusterResources fetches all K8s resources from the current cluster for visualization
 func (a *Agent) GetClusterResources(ctx context.Context, clusterName string, opts QueryOptions) (*ClusterResources, error) {
-	if a.debug {
-		fmt.Printf("[k8s-agent] getting cluster resources for: %s\n", clusterName)
-	}
+	logger.Debugf("getting cluster resources for: %s", clusterName)
 
-	// Initialize client if needed
-	if a.client == nil {
-		a.client = NewClient(opts.Kubeconfig, "", a.debug)
-	}
+	a = a.ensureClient(opts)
 
 	result := &ClusterResources{
 		ClusterName: clusterName,
@@ -1979,13 +2469,17 @@ func (a *Agent) GetClusterResources(ctx context.Context, clusterName string, opt
 		Ingresses:   []ClusterIngressInfo{},
 	}
 
-	// Get nodes
-	nodes, err := a.client.GetNodes(ctx)
-	if err != nil {
-		if a.debug {
-			fmt.Printf("[k8s-agent] warning: failed to get nodes: %v\n", err)
+	// Each resource is fetched on its own; one that fails or is slow
+	// does not hold back the rest
+	var g errgroup.Group
+	g.SetLimit(clusterResourceFetchLimit)
+
+	g.Go(func() error {
+		nodes, err := a.client.GetNodes(ctx)
+		if err != nil {
+			logger.Debugf("warning: failed to get nodes: %v", err)
+			return nil
 		}
-	} else {
 		for _, n := range nodes {
 			result.Nodes = append(result.Nodes, ClusterNodeInfo{
 				Name:       n.Name,
@@ -1997,79 +2491,138 @@ func (a *Agent) GetClusterResources(ctx context.Context, clusterName string, opt
 				Labels:     n.Labels,
 			})
 		}
-	}
+		return nil
+	})
 
-	// Get pods from all namespaces
-	pods, err := a.getPodsJSON(ctx)
-	if err != nil {
-		if a.debug {
-			fmt.Printf("[k8s-agent] warning: failed to get pods: %v\n", err)
+	// Pods from all namespaces, or only their counts when there are too
+	// many to show
+	g.Go(func() error {
+		pods, summary, err := a.client.podsOrSummary(ctx, "", opts.LabelSelector, opts.FieldSelector, opts.workloadOptions().SummaryLimit())
+		if err != nil {
+			logger.Debugf("warning: failed to get pods: %v", err)
+			return nil
+		}
+		if summary != nil {
+			result.PodSummary = summary
+			return nil
 		}
-	} else {
 		result.Pods = pods
-	}
+		return nil
+	})
 
-	// Get services from all namespaces
-	services, err := a.getServicesJSON(ctx)
-	if err != nil {
-		if a.debug {
-			fmt.Printf("[k8s-agent] warning: failed to get services: %v\n", err)
+	g.Go(func() error {
+		services, err := a.getServicesJSON(ctx, opts)
+		if err != nil {
+			logger.Debugf("warning: failed to get services: %v", err)
+			return nil
 		}
-	} else {
 		result.Services = services
-	}
+		return nil
+	})
 
-	// Get PVs (cluster scoped)
-	pvs, err := a.getPVsJSON(ctx)
-	if err != nil {
-		if a.debug {
-			fmt.Printf("[k8s-agent] warning: failed to get PVs: %v\n", err)
+	// PVs are cluster scoped
+	g.Go(func() error {
+		pvs, err := a.getPVsJSON(ctx)
+		if err != nil {
+			logger.Debugf("warning: failed to get PVs: %v", err)
+			return nil
 		}
-	} else {
 		result.PVs = pvs
-	}
+		return nil
+	})
 
-	// Get PVCs from all namespaces
-	pvcs, err := a.getPVCsJSON(ctx)
-	if err != nil {
-		if a.debug {
-			fmt.Printf("[k8s-agent] warning: failed to get PVCs: %v\n", err)
+	g.Go(func() error {
+		pvcs, err := a.getPVCsJSON(ctx, opts)
+		if err != nil {
+			logger.Debugf("warning: failed to get PVCs: %v", err)
+			return nil
 		}
-	} else {
 		result.PVCs = pvcs
-	}
+		return nil
+	})
 
-	// Get ConfigMaps from all namespaces
-	configMaps, err := a.getConfigMapsJSON(ctx)
-	if err != nil {
-		if a.debug {
-			fmt.Printf("[k8s-agent] warning: failed to get ConfigMaps: %v\n", err)
+	g.Go(func() error {
+		configMaps, err := a.getConfigMapsJSON(ctx, opts)
+		if err != nil {
+			logger.Debugf("warning: failed to get ConfigMaps: %v", err)
+			return nil
 		}
-	} else {
 		result.ConfigMaps = configMaps
-	}
+		return nil
+	})
 
-	// Get Ingresses from all namespaces
-	ingresses, err := a.getIngressesJSON(ctx)
-	if err != nil {
-		if a.debug {
-			fmt.Printf("[k8s-agent] warning: failed to get Ingresses: %v\n", err)
+	g.Go(func() error {
+		ingresses, err := a.getIngressesJSON(ctx, opts)
+		if err != nil {
+			logger.Debugf("warning: failed to get Ingresses: %v", err)
+			return nil
 		}
-	} else {
 		result.Ingresses = ingresses
-	}
+		return nil
+	})
+
+	_ = g.Wait()
 
 	return result, nil
 }
 
-// getPodsJSON fetches pods as structured data
-func (a *Agent) getPodsJSON(ctx context.Context) ([]ClusterPodInfo, error) {
-	output, err := a.client.RunJSON(ctx, "get", "pods", "--all-namespaces")
+// summarizePods counts pods per namespace and phase
+func summarizePods(pods []ClusterPodInfo) *workloads.PodSummary {
+	summary := workloads.NewPodSummary()
+	addPods(summary, pods)
+	return summary
+}
+
+// addPods counts pods into summary
+func addPods(summary *workloads.PodSummary, pods []ClusterPodInfo) {
+	for _, pod := range pods {
+		summary.Add(pod.Namespace, pod.Phase)
+	}
+}
+
+// podsOrSummary lists pods in namespace, or in every namespace when it is
+// empty, one page at a time. Once more than limit pods have arrived, the
+// ones kept so far are counted into a summary and dropped, and each later
+// page is counted and dropped as it arrives. A limit of 0 or less keeps
+// every pod.
+func (c *Client) podsOrSummary(ctx context.Context, namespace, labelSelector, fieldSelector string, limit int) ([]ClusterPodInfo, *workloads.PodSummary, error) {
+	var pods []ClusterPodInfo
+	var summary *workloads.PodSummary
+	err := c.pagePods(ctx, namespace, labelSelector, fieldSelector, func(page []ClusterPodInfo) {
+		switch {
+		case summary != nil:
+			addPods(summary, page)
+		case limit > 0 && len(pods)+len(page) > limit:
+			logger.Debugf("more than %d pods, the summary threshold; counting them instead", limit)
+			summary = summarizePods(pods)
+			addPods(summary, page)
+			pods = nil
+		default:
+			pods = append(pods, page...)
+		}
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	return pods, summary, nil
+}
+
+// selectorArgs narrows an all-namespaces list to the label selector in
+// opts. The field selector is written for pods, which podsOrSummary lists.
+func selectorArgs(opts QueryOptions) []string {
+	if opts.LabelSelector == "" {
+		return nil
 	}
+	return []string{"-l", opts.LabelSelector}
+}
 
+// parsePodList reads one page of a pod list as structured data, with the
+// token that continues it, empty on the last page
+func parsePodList(output []byte) ([]ClusterPodInfo, string, error) {
 	var podList struct {
+		Metadata struct {
+			Continue string `json:"continue"`
+		} `json:"metadata"`
 		Items []struct {
 			Metadata struct {
 				Name      string            `json:"name"`
@@ -2108,7 +2661,7 @@ func (a *Agent) getPodsJSON(ctx context.Context) ([]ClusterPodInfo, error) {
 	}
 
 	if err := json.Unmarshal(output, &podList); err != nil {
-		return nil, fmt.Errorf("failed to parse pods: %w", err)
+		return nil, "", fmt.Errorf("failed to parse pods: %w", err)
 	}
 
 	pods := make([]ClusterPodInfo, 0, len(podList.Items))
@@ -2178,7 +2731,7 @@ func (a *Agent) getPodsJSON(ctx context.Context) ([]ClusterPodInfo, error) {
 		pods = append(pods, pod)
 	}
 
-	return pods, nil
+	return pods, podList.Metadata.Continue, nil
 }
 
 // getContainerImage finds the image for a container by name
@@ -2195,8 +2748,8 @@ func getContainerImage(containers []struct {
 }
 
 // getServicesJSON fetches services as structured data
-func (a *Agent) getServicesJSON(ctx context.Context) ([]ClusterServiceInfo, error) {
-	output, err := a.client.RunJSON(ctx, "get", "services", "--all-namespaces")
+func (a *Agent) getServicesJSON(ctx context.Context, opts QueryOptions) ([]ClusterServiceInfo, error) {
+	output, err := a.client.RunJSON(ctx, append([]string{"get", "services", "--all-namespaces"}, selectorArgs(opts)...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -2332,8 +2885,8 @@ func (a *Agent) getPVsJSON(ctx context.Context) ([]ClusterPVInfo, error) {
 }
 
 // getPVCsJSON fetches persistent volume claims as structured data
-func (a *Agent) getPVCsJSON(ctx context.Context) ([]ClusterPVCInfo, error) {
-	output, err := a.client.RunJSON(ctx, "get", "pvc", "--all-namespaces")
+func (a *Agent) getPVCsJSON(ctx context.Context, opts QueryOptions) ([]ClusterPVCInfo, error) {
+	output, err := a.client.RunJSON(ctx, append([]string{"get", "pvc", "--all-namespaces"}, selectorArgs(opts)...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -2387,8 +2940,8 @@ func (a *Agent) getPVCsJSON(ctx context.Context) ([]ClusterPVCInfo, error) {
 }
 
 // getConfigMapsJSON fetches config maps as structured data
-func (a *Agent) getConfigMapsJSON(ctx context.Context) ([]ClusterConfigMapInfo, error) {
-	output, err := a.client.RunJSON(ctx, "get", "configmaps", "--all-namespaces")
+func (a *Agent) getConfigMapsJSON(ctx context.Context, opts QueryOptions) ([]ClusterConfigMapInfo, error) {
+	output, err := a.client.RunJSON(ctx, append([]string{"get", "configmaps", "--all-namespaces"}, selectorArgs(opts)...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -2425,8 +2978,8 @@ func (a *Agent) getConfigMapsJSON(ctx context.Context) ([]ClusterConfigMapInfo,
 }
 
 // getIngressesJSON fetches ingresses as structured data
-func (a *Agent) getIngressesJSON(ctx context.Context) ([]ClusterIngressInfo, error) {
-	output, err := a.client.RunJSON(ctx, "get", "ingress", "--all-namespaces")
+func (a *Agent) getIngressesJSON(ctx context.Context, opts QueryOptions) ([]ClusterIngressInfo, error) {
+	output, err := a.client.RunJSON(ctx, append([]string{"get", "ingress", "--all-namespaces"}, selectorArgs(opts)...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -2531,7 +3084,8 @@ func IsK8sQuery(question string) bool {
 		// Tools
 		"helm", "chart", "release", "tiller",
 		// Providers
-		"eks", "kubeadm", "kops", "k3s", "minikube",
+		"eks", "kubeadm", "kops", "k3s", "minikube", "openshift", "okd",
+		"deploymentconfig", "securitycontextconstraint",
 		// Operations
 		"rollout", "scale", "drain", "cordon", "taint",
 	}
@@ -2564,6 +3118,12 @@ func DetectCloudProvider(contextName, clusterName string) CloudProvider {
 func DetectCloudProviderFromContext(contextName string) CloudProvider {
 	contextLower := strings.ToLower(contextName)
 
+	// OpenShift first: `oc login` contexts embed the API host, which often
+	// names the underlying cloud (api-prod-aws-example-com:6443)
+	if isOpenShiftContext(contextName) {
+		return CloudProviderOpenShift
+	}
+
 	// GKE context pattern: gke_PROJECT_REGION_CLUSTER
 	if strings.HasPrefix(contextLower, "gke_") {
 		return CloudProviderGCP
@@ -2596,6 +3156,10 @@ func DetectCloudProviderFromContext(contextName string) CloudProvider {
 func DetectCloudProviderFromClusterName(clusterName string) CloudProvider {
 	nameLower := strings.ToLower(clusterName)
 
+	if openShiftNamePattern.MatchString(nameLower) {
+		return CloudProviderOpenShift
+	}
+
 	// GKE clusters often have gke in the name
 	if strings.Contains(nameLower, "gke") {
 		return CloudProviderGCP
@@ -2618,6 +3182,18 @@ func DetectCloudProviderFromClusterName(clusterName string) CloudProvider {
 func DetectCloudProviderFromQuery(query string) CloudProvider {
 	queryLower := strings.ToLower(query)
 
+	// OpenShift-specific terms; checked first since ROSA and ARO queries
+	// also mention AWS and Azure
+	openShiftTerms := []string{
+		"openshift", "okd", "deploymentconfig", "deployment config",
+		"route.openshift.io", "security context constraint", " scc",
+	}
+	for _, term := range openShiftTerms {
+		if strings.Contains(queryLower, term) {
+			return CloudProviderOpenShift
+		}
+	}
+
 	// GKE-specific terms
 	gkeTerms := []string{
 		"gke", "gcp", "google cloud", "artifact registry",
@@ -2657,12 +3233,19 @@ func DetectCloudProviderFromQuery(query string) CloudProvider {
 
 // GetCloudProvider returns the currently detected cloud provider
 func (a *Agent) GetCloudProvider() CloudProvider {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	return a.cloudProvider
 }
 
 // SetCloudProvider sets the cloud provider for the agent
 func (a *Agent) SetCloudProvider(provider CloudProvider) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	a.cloudProvider = provider
+	for _, scoped := range a.contexts {
+		scoped.SetCloudProvider(provider)
+	}
 }
 
 // DetectAndSetCloudProvider detects and sets the cloud provider from context
@@ -2676,11 +3259,22 @@ func (a *Agent) DetectAndSetCloudProvider(ctx context.Context) CloudProvider {
 	if err == nil && currentContext != "" {
 		provider := DetectCloudProviderFromContext(currentContext)
 		if provider != CloudProviderUnknown {
-			a.cloudProvider = provider
+			a.SetCloudProvider(provider)
+			if provider == CloudProviderOpenShift {
+				a.client.UseOcIfAvailable()
+			}
 			return provider
 		}
 	}
 
+	// Context names of self-managed clusters rarely say OpenShift, but the
+	// API groups do
+	if info, err := a.client.DetectOpenShift(ctx); err == nil && info.IsOpenShift() {
+		a.SetCloudProvider(CloudProviderOpenShift)
+		a.client.UseOcIfAvailable()
+		return CloudProviderOpenShift
+	}
+
 	return CloudProviderUnknown
 }
 
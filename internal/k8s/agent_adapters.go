@@ -3,10 +3,14 @@ package k8s
 import (
 	"context"
 
+	"github.com/bgdnvk/clanker/internal/k8s/backup"
 	"github.com/bgdnvk/clanker/internal/k8s/cost"
+	"github.com/bgdnvk/clanker/internal/k8s/helm"
 	"github.com/bgdnvk/clanker/internal/k8s/networking"
+	"github.com/bgdnvk/clanker/internal/k8s/openshift"
 	"github.com/bgdnvk/clanker/internal/k8s/sre"
 	"github.com/bgdnvk/clanker/internal/k8s/storage"
+	"github.com/bgdnvk/clanker/internal/k8s/telemetry"
 	"github.com/bgdnvk/clanker/internal/k8s/workloads"
 )
 
@@ -135,6 +139,12 @@ func (a *storageClientAdapter) Apply(ctx context.Context, manifest string) (stri
 	return a.client.Apply(ctx, manifest, "")
 }
 
+// NewHelmAdapter returns a helm.HelmClient backed by the given Client, for
+// callers outside this package that drive helm managers directly
+func NewHelmAdapter(client *Client) helm.HelmClient {
+	return &helmClientAdapter{client: client}
+}
+
 // helmClientAdapter wraps Client to implement helm.HelmClient interface
 type helmClientAdapter struct {
 	client *Client
@@ -172,6 +182,12 @@ func (a *sreClientAdapter) RunJSON(ctx context.Context, args ...string) ([]byte,
 	return a.client.RunJSON(ctx, args...)
 }
 
+// NewTelemetryAdapter returns a telemetry.K8sClient backed by the given
+// kubectl Client. Exposed for the `clanker k8s capacity` subcommand.
+func NewTelemetryAdapter(client *Client) telemetry.K8sClient {
+	return &telemetryClientAdapter{client: client}
+}
+
 // telemetryClientAdapter wraps Client to implement telemetry.K8sClient interface
 type telemetryClientAdapter struct {
 	client *Client
@@ -193,6 +209,74 @@ func (a *telemetryClientAdapter) GetJSON(ctx context.Context, resourceType, name
 	return a.client.GetJSON(ctx, resourceType, name, namespace)
 }
 
+// rbacClientAdapter wraps Client to implement rbac.K8sClient interface
+type rbacClientAdapter struct {
+	client *Client
+}
+
+func (a *rbacClientAdapter) Run(ctx context.Context, args ...string) (string, error) {
+	return a.client.Run(ctx, args...)
+}
+
+func (a *rbacClientAdapter) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	return a.client.RunWithNamespace(ctx, namespace, args...)
+}
+
+func (a *rbacClientAdapter) RunJSON(ctx context.Context, args ...string) ([]byte, error) {
+	return a.client.RunJSON(ctx, args...)
+}
+
+// NewOpenShiftAdapter returns an openshift.K8sClient backed by the given
+// Client. Call UseOcIfAvailable on the client first to run the queries
+// through oc rather than kubectl.
+func NewOpenShiftAdapter(client *Client) openshift.K8sClient {
+	return &openshiftClientAdapter{client: client}
+}
+
+// openshiftClientAdapter wraps Client to implement openshift.K8sClient interface
+type openshiftClientAdapter struct {
+	client *Client
+}
+
+func (a *openshiftClientAdapter) Run(ctx context.Context, args ...string) (string, error) {
+	return a.client.Run(ctx, args...)
+}
+
+func (a *openshiftClientAdapter) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	return a.client.RunWithNamespace(ctx, namespace, args...)
+}
+
+func (a *openshiftClientAdapter) RunJSON(ctx context.Context, args ...string) ([]byte, error) {
+	return a.client.RunJSON(ctx, args...)
+}
+
+// NewBackupAdapter returns a backup.K8sClient backed by the given kubectl
+// Client, so cmd/ can take a Velero backup before an upgrade
+func NewBackupAdapter(client *Client) backup.K8sClient {
+	return &backupClientAdapter{client: client}
+}
+
+// backupClientAdapter wraps Client to implement backup.K8sClient interface
+type backupClientAdapter struct {
+	client *Client
+}
+
+func (a *backupClientAdapter) Run(ctx context.Context, args ...string) (string, error) {
+	return a.client.Run(ctx, args...)
+}
+
+func (a *backupClientAdapter) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	return a.client.RunWithNamespace(ctx, namespace, args...)
+}
+
+func (a *backupClientAdapter) RunJSON(ctx context.Context, args ...string) ([]byte, error) {
+	return a.client.RunJSON(ctx, args...)
+}
+
+func (a *backupClientAdapter) Apply(ctx context.Context, manifest string, namespace string) (string, error) {
+	return a.client.Apply(ctx, manifest, namespace)
+}
+
 // NewK8sCostAdapter returns a cost.K8sClient backed by the given kubectl
 // Client. Exposed so callers outside this package can build the workload
 // cost attributor without poking at the unexported adapter type.
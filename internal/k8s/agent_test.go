@@ -0,0 +1,339 @@
+package k8s
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/spf13/viper"
+)
+
+func TestParsePlanResponseBuildsManifestsFromSpecs(t *testing.T) {
+	response := "Here is the plan:\n" + `{
+  "summary": "Deploy the api",
+  "kubectl_cmds": [{"args": ["create", "namespace", "shop"], "reason": "namespace for the api"}],
+  "apps": [
+    {"reason": "serve the api", "spec": {"name": "api", "namespace": "shop", "image": "myorg/api:1.2", "port": 8080,
+      "resources": {"cpu_request": "250m", "memory_limit": "512Mi"}, "probe": {"path": "/healthz"}}},
+    {"reason": "broken", "spec": {"name": "Bad_Name", "image": "nginx"}}
+  ],
+  "notes": ["api listens on 8080"]
+}`
+
+	a := &Agent{}
+	plan := &K8sPlan{}
+	if err := a.parsePlanResponse(response, plan); err != nil {
+		t.Fatalf("parsePlanResponse: %v", err)
+	}
+
+	if plan.Summary != "Deploy the api" || len(plan.KubectlCmds) != 1 || len(plan.Notes) != 1 {
+		t.Errorf("plan = %+v", plan)
+	}
+
+	var kinds []string
+	for _, m := range plan.Manifests {
+		kinds = append(kinds, m.Kind)
+		if m.Reason != "serve the api" || m.Namespace != "shop" {
+			t.Errorf("%s: reason=%q namespace=%q", m.Kind, m.Reason, m.Namespace)
+		}
+	}
+	if strings.Join(kinds, ",") != "Deployment,Service" {
+		t.Errorf("kinds = %v, want Deployment,Service", kinds)
+	}
+	if !strings.Contains(plan.Manifests[0].Content, "path: /healthz") || !strings.Contains(plan.Manifests[0].Content, "cpu: 250m") {
+		t.Errorf("deployment missing probe or resources:\n%s", plan.Manifests[0].Content)
+	}
+
+	if len(plan.Warnings) != 1 || !strings.Contains(plan.Warnings[0], `skipped application "Bad_Name"`) {
+		t.Errorf("warnings = %v", plan.Warnings)
+	}
+}
+
+func TestParsePlanResponseRejectsInvalidJSON(t *testing.T) {
+	a := &Agent{}
+	if err := a.parsePlanResponse("no plan here", &K8sPlan{}); err == nil {
+		t.Error("expected an error without JSON")
+	}
+	if err := a.parsePlanResponse(`{"summary": "x", "apps": "not a list"}`, &K8sPlan{}); err == nil {
+		t.Error("expected an error for malformed apps")
+	}
+}
+
+func TestFormatDiagnosticReportShowsTrackedErrors(t *testing.T) {
+	out := formatDiagnosticReport(&sre.DiagnosticReport{
+		Summary:      "deployment api has 1 issues",
+		Scope:        "resource",
+		ResourceType: "deployment",
+		ResourceName: "api",
+		TrackedErrors: []sre.TrackedError{{
+			Source: "sentry", ID: "BACKEND-42", Title: "TypeError", Events: "1520", Users: 310,
+			Link: "https://acme.sentry.io/issues/4012345/",
+		}},
+	})
+	for _, want := range []string{"Related Errors:", "[sentry] BACKEND-42: TypeError (1520 events, 310 users)", "https://acme.sentry.io/issues/4012345/"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// Run with -race: queries from a server or watch loop share one agent
+func TestHandleQueryConcurrent(t *testing.T) {
+	// With no kubectl, oc or helm on PATH every command fails at once
+	t.Setenv("PATH", t.TempDir())
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	opts := QueryOptions{Kubeconfig: kubeconfig, Namespace: "default", Context: "prod"}
+
+	a := &Agent{}
+	a.ensureClient(opts)
+	client, workloads := a.client, a.workloads
+
+	queries := []string{"list pods", "show services", "list pvcs", "list helm releases", "list routes", "can I delete pods"}
+	contexts := []string{"prod", "staging", "dev", ""}
+	var wg sync.WaitGroup
+	for _, kubeContext := range contexts {
+		for _, q := range queries {
+			wg.Add(1)
+			go func(q, kubeContext string) {
+				defer wg.Done()
+				o := opts
+				o.Context = kubeContext
+				_, _ = a.HandleQuery(context.Background(), q, o)
+			}(q, kubeContext)
+		}
+	}
+	wg.Wait()
+
+	_, _ = a.HandleQuery(context.Background(), "list pods", opts)
+	if a.client != client || a.client.context != "prod" || a.workloads != workloads || a.openshift == nil {
+		t.Error("the shared client or sub-agents were switched, recreated or left nil")
+	}
+
+	// Every other context got a client of its own, kept for later queries
+	if len(a.contexts) != 2 {
+		t.Fatalf("%d context agents, want staging and dev", len(a.contexts))
+	}
+	for _, kubeContext := range []string{"staging", "dev"} {
+		scoped := a.contexts[contextKey{kubeconfig: kubeconfig, context: kubeContext}]
+		if scoped == nil || scoped.client == client || scoped.client.context != kubeContext || scoped.workloads == nil {
+			t.Errorf("context %s: agent %+v, want its own client and sub-agents", kubeContext, scoped)
+			continue
+		}
+		if got := a.ensureClient(QueryOptions{Context: kubeContext}); got != scoped {
+			t.Errorf("context %s: a later query got another agent", kubeContext)
+		}
+	}
+}
+
+func TestEnsureClientKeysOnKubeconfig(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	dir := t.TempDir()
+	a := &Agent{}
+	a.ensureClient(QueryOptions{Kubeconfig: filepath.Join(dir, "config")})
+
+	if got := a.ensureClient(QueryOptions{}); got != a {
+		t.Error("a query naming nothing got another agent")
+	}
+	other := filepath.Join(dir, "other")
+	scoped := a.ensureClient(QueryOptions{Kubeconfig: other})
+	if scoped == a || scoped.client.kubeconfig != other || scoped.client.context != "" {
+		t.Fatalf("a query naming another kubeconfig got kubeconfig %q context %q", scoped.client.kubeconfig, scoped.client.context)
+	}
+	if got := a.ensureClient(QueryOptions{Kubeconfig: other}); got != scoped {
+		t.Error("a later query for the other kubeconfig got another agent")
+	}
+}
+
+func TestScopedAgentsFollowSetters(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	a := &Agent{}
+	a.ensureClient(QueryOptions{Context: "prod"})
+	scoped := a.ensureClient(QueryOptions{Context: "staging"})
+	if scoped == a {
+		t.Fatal("context staging got the shared agent")
+	}
+
+	decide := func(ctx context.Context, prompt string) (string, error) { return "decision", nil }
+	model := func(ctx context.Context, prompt string) (string, error) { return "model", nil }
+	a.SetAIDecisionFunction(decide)
+	a.SetQueryModel(model)
+	a.SetCloudProvider(CloudProviderGCP)
+
+	if scoped.aiDecisionFn == nil {
+		t.Error("scoped agent has no AI decision function")
+	} else if out, _ := scoped.aiDecisionFn(context.Background(), ""); out != "decision" {
+		t.Errorf("scoped AI decision function answered %q", out)
+	}
+	if fn := scoped.queryModel(); fn == nil {
+		t.Error("scoped agent has no query model")
+	} else if out, _ := fn(context.Background(), ""); out != "model" {
+		t.Errorf("scoped query model answered %q", out)
+	}
+	if got := scoped.GetCloudProvider(); got != CloudProviderGCP {
+		t.Errorf("scoped cloud provider = %q, want gcp", got)
+	}
+}
+
+func TestGetClusterResourcesConcurrent(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	// Every call waits long enough for the others to start, records how
+	// many were running, and services fail
+	dir := t.TempDir()
+	script := `#!/bin/sh
+touch ` + dir + `/run.$$
+sleep 0.3
+ls ` + dir + ` | grep -c '^run\.' >> ` + dir + `/running
+rm ` + dir + `/run.$$
+case "$*" in *services*) echo forbidden >&2; exit 1;; esac
+echo '{"items":[{"metadata":{"name":"x","namespace":"default"}}]}'
+`
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "kubectl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	invalidateReads()
+
+	a := &Agent{}
+	got, err := a.GetClusterResources(context.Background(), "test", QueryOptions{Context: "resources-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "running"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	most := 0
+	for _, line := range strings.Fields(string(data)) {
+		if n, _ := strconv.Atoi(line); n > most {
+			most = n
+		}
+	}
+	if most < 2 {
+		t.Errorf("fetches did not overlap: at most %d kubectl call ran at once", most)
+	}
+	if most > clusterResourceFetchLimit {
+		t.Errorf("%d kubectl calls ran at once, limit is %d", most, clusterResourceFetchLimit)
+	}
+
+	if len(got.Services) != 0 {
+		t.Errorf("services = %v, want none after the failure", got.Services)
+	}
+	if len(got.Nodes) != 1 || len(got.Pods) != 1 || len(got.PVs) != 1 || len(got.PVCs) != 1 || len(got.ConfigMaps) != 1 || len(got.Ingresses) != 1 {
+		t.Errorf("a failed resource dropped others: %+v", got)
+	}
+}
+
+// pagedKubectl puts a kubectl on PATH that serves five pods in pages of
+// two, following the continue token, and logs each call to the returned
+// file
+func pagedKubectl(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	script := `#!/bin/sh
+echo "$*" >> ` + calls + `
+case "$*" in
+*pods*continue=page3*) echo '{"metadata":{},"items":[
+  {"metadata":{"name":"api-2","namespace":"api"},"status":{"phase":"Failed"}}]}';;
+*pods*continue=page2*) echo '{"metadata":{"continue":"page3"},"items":[
+  {"metadata":{"name":"web-3","namespace":"web"},"status":{"phase":"Running"}},
+  {"metadata":{"name":"api-1","namespace":"api"},"status":{"phase":"Running"}}]}';;
+*pods*) echo '{"metadata":{"continue":"page2"},"items":[
+  {"metadata":{"name":"web-1","namespace":"web"},"spec":{"nodeName":"node-a"},"status":{"phase":"Running","podIP":"10.0.0.1"}},
+  {"metadata":{"name":"web-2","namespace":"web"},"status":{"phase":"Pending","containerStatuses":[{"name":"app","state":{"waiting":{"reason":"ImagePullBackOff"}}}]}}]}';;
+*) echo '{"items":[]}';;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, "kubectl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	invalidateReads()
+	return calls
+}
+
+// podCalls returns the pod listings logged to calls
+func podCalls(t *testing.T, calls string) []string {
+	t.Helper()
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.Contains(line, "pods") {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func TestGetClusterResourcesSummarizesPodPages(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("kubernetes.chunk_size", 2)
+	calls := pagedKubectl(t)
+
+	a := &Agent{}
+	got, err := a.GetClusterResources(context.Background(), "test", QueryOptions{
+		Context:     "pod-pages-test",
+		ListOptions: ListOptions{LabelSelector: "tier=app", SummaryThreshold: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := got.PodSummary
+	if summary == nil || summary.Total != 5 || summary.Namespaces["web"]["Running"] != 2 || summary.Namespaces["api"]["Failed"] != 1 || len(got.Pods) != 0 {
+		t.Errorf("pods = %v, summary = %+v, want only a summary of 5", got.Pods, summary)
+	}
+
+	listings := podCalls(t, calls)
+	if len(listings) != 3 {
+		t.Fatalf("pod listings = %q, want one per page", listings)
+	}
+	for i, want := range []string{"", "continue=page2", "continue=page3"} {
+		if !strings.Contains(listings[i], "get --raw /api/v1/pods?") || !strings.Contains(listings[i], "limit=2") ||
+			!strings.Contains(listings[i], "labelSelector=tier%3Dapp") || !strings.Contains(listings[i], want) {
+			t.Errorf("listing %d = %q, want page %d of two pods", i, listings[i], i+1)
+		}
+	}
+}
+
+func TestListPodsPages(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("kubernetes.chunk_size", 2)
+	calls := pagedKubectl(t)
+
+	c := NewClient("", "pod-pages-test", false)
+	c.SetListOptions(ListOptions{SummaryThreshold: 10})
+	out, err := c.listPods(context.Background(), "", true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"NAMESPACE", "web-1", "10.0.0.1", "node-a", "ImagePullBackOff", "api-2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("table missing %q:\n%s", want, out)
+		}
+	}
+	if listings := podCalls(t, calls); len(listings) != 3 {
+		t.Errorf("pod listings = %q, want one per page", listings)
+	}
+
+	c.SetListOptions(ListOptions{SummaryThreshold: 4})
+	out, err = c.listPods(context.Background(), "", true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "5 pods in 2 namespaces") {
+		t.Errorf("listing over the threshold = %q, want a summary", out)
+	}
+}
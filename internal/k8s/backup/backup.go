@@ -0,0 +1,249 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/log"
+)
+
+var logger = log.New("k8s.backup")
+
+// SubAgent handles Velero backup and restore queries
+type SubAgent struct {
+	client K8sClient
+	debug  bool
+}
+
+// NewSubAgent creates a new backup sub-agent
+func NewSubAgent(client K8sClient, debug bool) *SubAgent {
+	return &SubAgent{
+		client: client,
+		debug:  debug,
+	}
+}
+
+// HandleQuery processes a backup query. Installs, backups and restores are
+// returned as plans; listing runs immediately.
+func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
+	logger.Debugf("handling query: %s", query)
+
+	intent, _ := ParseQuery(query)
+	if intent.Provider == "" {
+		intent.Provider = opts.Provider
+	}
+
+	logger.Debugf("intent: action=%s, backup=%s, namespaces=%v, target=%s", intent.Action, intent.Backup, intent.Namespaces, intent.TargetNamespace)
+
+	if intent.Action == ActionInstall {
+		plan := InstallPlan(InstallOptions{Provider: intent.Provider, Bucket: intent.Bucket})
+		if s.Installed(ctx) {
+			plan.Notes = append(plan.Notes, "Velero is already installed; the plan upgrades it in place")
+		}
+		return &Response{Type: ResponseTypePlan, Plan: plan, Message: plan.Summary}, nil
+	}
+
+	if !s.Installed(ctx) {
+		return &Response{
+			Type:    ResponseTypeResult,
+			Message: "Velero is not installed in this cluster. Ask to \"install velero with bucket <name>\" first.",
+		}, nil
+	}
+
+	switch intent.Action {
+	case ActionBackup:
+		plan := CreateBackupPlan(BackupOptions{Namespaces: intent.Namespaces, TTL: intent.TTL})
+		return &Response{Type: ResponseTypePlan, Plan: plan, Message: plan.Summary}, nil
+	case ActionRestore:
+		return s.handleRestore(ctx, intent)
+	default:
+		backups, err := s.ListBackups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Type: ResponseTypeResult, Data: FormatBackups(backups, time.Now())}, nil
+	}
+}
+
+// handleRestore plans a restore from the named backup, or from the latest
+// completed backup of the namespaces when none is named
+func (s *SubAgent) handleRestore(ctx context.Context, intent Intent) (*Response, error) {
+	name := intent.Backup
+	if name == "" {
+		backups, err := s.ListBackups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		latest, ok := LatestBackup(backups, intent.Namespaces)
+		if !ok {
+			scope := "the cluster"
+			if len(intent.Namespaces) > 0 {
+				scope = "namespace " + strings.Join(intent.Namespaces, ", ")
+			}
+			return &Response{Type: ResponseTypeResult, Message: fmt.Sprintf("No completed Velero backup of %s found", scope)}, nil
+		}
+		name = latest.Name
+	}
+
+	plan := RestorePlan(RestoreOptions{
+		Backup:          name,
+		Namespaces:      intent.Namespaces,
+		TargetNamespace: intent.TargetNamespace,
+	})
+	if intent.TargetNamespace != "" && len(intent.Namespaces) != 1 {
+		plan.Warnings = append(plan.Warnings, "A new namespace name needs exactly one source namespace; restoring under the original names")
+	}
+	return &Response{Type: ResponseTypePlan, Plan: plan, Message: plan.Summary}, nil
+}
+
+var (
+	namespaceListPattern   = regexp.MustCompile(`\bnamespaces?\s+([a-z0-9][a-z0-9-]*(?:(?:\s*,\s*|\s+and\s+)[a-z0-9][a-z0-9-]*)*)`)
+	namespaceBeforePattern = regexp.MustCompile(`\b([a-z0-9][a-z0-9-]*)(?:\s+and\s+([a-z0-9][a-z0-9-]*))?\s+namespaces?\b`)
+	namespaceSeparator     = regexp.MustCompile(`\s*,\s*|\s+and\s+`)
+	backupNamePattern      = regexp.MustCompile(`\bbackup\s+(?:named\s+|called\s+)?([a-z0-9][a-z0-9.-]*)`)
+	restoreFromPattern     = regexp.MustCompile(`\bfrom\s+(?:the\s+)?(?:velero\s+)?(?:backup\s+)?([a-z0-9][a-z0-9.-]*)`)
+	restoreTargetPattern   = regexp.MustCompile(`\b(?:into|as)\s+(?:the\s+)?(?:new\s+)?(?:namespace\s+)?([a-z0-9][a-z0-9-]*)`)
+	ttlPattern             = regexp.MustCompile(`\b(?:keep|retain|ttl|expir\w*)\D{0,20}?(\d+)\s*(hours?|h|days?|d|weeks?|w)\b`)
+	bucketPattern          = regexp.MustCompile(`\b(?:bucket|container)\s+(?:named\s+|called\s+)?([a-z0-9][a-z0-9._-]*)|\b(?:s3|gs)://([a-z0-9][a-z0-9._-]*)`)
+
+	// providerPatterns pick the object storage provider named in a query
+	providerPatterns = []struct {
+		provider string
+		pattern  *regexp.Regexp
+	}{
+		{"aws", regexp.MustCompile(`\b(?:s3|aws|eks)\b`)},
+		{"gcp", regexp.MustCompile(`\b(?:gcs|gs://|gcp|google|gke)\b`)},
+		{"azure", regexp.MustCompile(`\b(?:azure|blob|aks)\b`)},
+	}
+
+	// notNames are words the name patterns pick up that are not names
+	notNames = map[string]bool{
+		"the": true, "a": true, "an": true, "all": true, "every": true, "each": true, "whole": true,
+		"of": true, "for": true, "from": true, "to": true, "into": true, "and": true, "my": true,
+		"this": true, "that": true, "new": true, "same": true, "original": true, "target": true,
+		"latest": true, "last": true, "velero": true, "backup": true, "backups": true, "in": true,
+		"keep": true, "retain": true, "expire": true, "restore": true, "then": true, "with": true,
+		"using": true, "it": true, "them": true, "cluster": true, "before": true, "after": true,
+	}
+)
+
+// MatchesQuery reports whether the query is about Velero backups
+func MatchesQuery(query string) bool {
+	_, ok := ParseQuery(query)
+	return ok
+}
+
+// ParseQuery reads requests such as "install velero with bucket my-backups",
+// "back up namespace shop and keep it for 7 days", "restore namespace shop
+// from the latest backup into shop-restored" or "list velero backups"
+func ParseQuery(query string) (Intent, bool) {
+	q := strings.ToLower(query)
+	velero := strings.Contains(q, "velero")
+	if !velero && !containsAny(q, []string{"backup", "back up", "back-up"}) {
+		return Intent{}, false
+	}
+	// Troubleshooting goes to the sre sub-agent; etcd snapshots, database
+	// dumps and backup CronJobs are not Velero's job
+	if strings.HasPrefix(q, "why") ||
+		(!velero && containsAny(q, []string{"etcd", "pg_dump", "mysqldump", "cronjob", "cron job"})) {
+		return Intent{}, false
+	}
+
+	intent := Intent{Action: ActionList}
+	switch {
+	case velero && containsAny(q, []string{"install", "set up", "setup", "deploy"}):
+		intent.Action = ActionInstall
+	case strings.Contains(q, "restore"):
+		intent.Action = ActionRestore
+	case strings.HasPrefix(q, "list") || strings.HasPrefix(q, "show") || strings.HasPrefix(q, "what") ||
+		strings.HasPrefix(q, "which") || strings.HasPrefix(q, "how") || strings.HasPrefix(q, "when"):
+		intent.Action = ActionList
+	case containsAny(q, []string{"back up", "back-up", "take a", "create a", "create backup", "run a", "trigger", "make a"}) ||
+		strings.HasPrefix(q, "backup "):
+		intent.Action = ActionBackup
+	}
+
+	intent.Namespaces = parseNamespaces(q)
+	if intent.Action == ActionRestore {
+		for _, pattern := range []*regexp.Regexp{backupNamePattern, restoreFromPattern} {
+			if m := pattern.FindStringSubmatch(q); m != nil && !notNames[m[1]] && !strings.Contains(q, "namespace "+m[1]) {
+				intent.Backup = m[1]
+				break
+			}
+		}
+		if m := restoreTargetPattern.FindStringSubmatch(q); m != nil && !notNames[m[1]] {
+			intent.TargetNamespace = m[1]
+			intent.Namespaces = without(intent.Namespaces, m[1])
+		}
+	}
+	if m := ttlPattern.FindStringSubmatch(q); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := time.Hour
+		switch m[2][0] {
+		case 'd':
+			unit = 24 * time.Hour
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		}
+		intent.TTL = time.Duration(n) * unit
+	}
+	if m := bucketPattern.FindStringSubmatch(q); m != nil {
+		intent.Bucket = m[1] + m[2]
+	}
+	for _, p := range providerPatterns {
+		if p.pattern.MatchString(q) {
+			intent.Provider = p.provider
+			break
+		}
+	}
+
+	return intent, true
+}
+
+// parseNamespaces returns the namespaces a query names, in order
+func parseNamespaces(q string) []string {
+	var namespaces []string
+	seen := make(map[string]bool)
+	add := func(ns string) {
+		if ns != "" && !notNames[ns] && !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+
+	for _, m := range namespaceListPattern.FindAllStringSubmatch(q, -1) {
+		for _, part := range namespaceSeparator.Split(m[1], -1) {
+			add(strings.TrimSpace(part))
+		}
+	}
+	if len(namespaces) == 0 {
+		for _, m := range namespaceBeforePattern.FindAllStringSubmatch(q, -1) {
+			add(m[1])
+			add(m[2])
+		}
+	}
+	return namespaces
+}
+
+func without(items []string, item string) []string {
+	var kept []string
+	for _, i := range items {
+		if i != item {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
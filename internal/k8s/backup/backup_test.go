@@ -0,0 +1,249 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockK8sClient implements K8sClient for testing. RunJSON answers from
+// jsonByResource keyed on the resource argument.
+type mockK8sClient struct {
+	installed      bool
+	jsonByResource map[string]string
+	applied        []string
+}
+
+func (m *mockK8sClient) Run(ctx context.Context, args ...string) (string, error) {
+	if len(args) > 2 && args[1] == "crd" && !m.installed {
+		return "", errors.New(`customresourcedefinitions.apiextensions.k8s.io "backups.velero.io" not found`)
+	}
+	return "", nil
+}
+
+func (m *mockK8sClient) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	return m.Run(ctx, args...)
+}
+
+func (m *mockK8sClient) RunJSON(ctx context.Context, args ...string) ([]byte, error) {
+	if out, ok := m.jsonByResource[args[1]]; ok {
+		return []byte(out), nil
+	}
+	return nil, errors.New("the server doesn't have a resource type " + args[1])
+}
+
+func (m *mockK8sClient) Apply(ctx context.Context, manifest string, namespace string) (string, error) {
+	m.applied = append(m.applied, manifest)
+	return "created", nil
+}
+
+func testClient() *mockK8sClient {
+	return &mockK8sClient{installed: true, jsonByResource: map[string]string{
+		"backups.velero.io": `{"items":[
+			{"metadata":{"name":"shop-20240101-1200","creationTimestamp":"2024-01-01T12:00:00Z"},
+			 "spec":{"includedNamespaces":["shop"],"storageLocation":"default"},
+			 "status":{"phase":"Completed","expiration":"2024-01-31T12:00:00Z","progress":{"itemsBackedUp":42}}},
+			{"metadata":{"name":"nightly-20240102-0000","creationTimestamp":"2024-01-02T00:00:00Z"},
+			 "spec":{"includedNamespaces":["*"]},
+			 "status":{"phase":"PartiallyFailed","expiration":"2024-02-01T00:00:00Z","errors":2,"progress":{"itemsBackedUp":310}}},
+			{"metadata":{"name":"billing-20231201-1200","creationTimestamp":"2023-12-01T12:00:00Z"},
+			 "spec":{"includedNamespaces":["billing"]},
+			 "status":{"phase":"Completed","expiration":"2023-12-31T12:00:00Z","progress":{"itemsBackedUp":12}}}
+		]}`,
+		"podvolumebackups.velero.io": `{"items":[
+			{"metadata":{"labels":{"velero.io/backup-name":"shop-20240101-1200"}},"status":{"progress":{"totalBytes":1073741824}}},
+			{"metadata":{"labels":{"velero.io/backup-name":"shop-20240101-1200"}},"status":{"progress":{"totalBytes":536870912}}}
+		]}`,
+	}}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Intent
+		ok    bool
+	}{
+		{"install velero with bucket my-backups on s3", Intent{Action: ActionInstall, Bucket: "my-backups", Provider: "aws"}, true},
+		{"set up velero backups to gs://cluster-backups", Intent{Action: ActionInstall, Bucket: "cluster-backups", Provider: "gcp"}, true},
+		{"back up namespace shop and keep it for 7 days", Intent{Action: ActionBackup, Namespaces: []string{"shop"}, TTL: 7 * 24 * time.Hour}, true},
+		{"back up the shop and billing namespaces", Intent{Action: ActionBackup, Namespaces: []string{"shop", "billing"}}, true},
+		{"take a velero backup of namespaces shop, billing", Intent{Action: ActionBackup, Namespaces: []string{"shop", "billing"}}, true},
+		{"create a backup of the whole cluster and retain it for 2 weeks", Intent{Action: ActionBackup, TTL: 14 * 24 * time.Hour}, true},
+		{"list velero backups", Intent{Action: ActionList}, true},
+		{"show me the backups with their sizes", Intent{Action: ActionList}, true},
+		{"restore namespace shop from backup shop-20240101-1200", Intent{Action: ActionRestore, Backup: "shop-20240101-1200", Namespaces: []string{"shop"}}, true},
+		{"restore namespace shop from the latest backup into shop-restored", Intent{Action: ActionRestore, Namespaces: []string{"shop"}, TargetNamespace: "shop-restored"}, true},
+		{"restore the shop namespace from velero backup nightly-20240102-0000", Intent{Action: ActionRestore, Backup: "nightly-20240102-0000", Namespaces: []string{"shop"}}, true},
+		{"why did the velero backup fail", Intent{}, false},
+		{"take an etcd backup before upgrading", Intent{}, false},
+		{"the backup cronjob keeps failing", Intent{}, false},
+		{"scale deployment web to 3", Intent{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseQuery(tt.query)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseQuery(%q) = %+v, %v, want %+v, %v", tt.query, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBackupManifest(t *testing.T) {
+	backupNow = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC) }
+	defer func() { backupNow = time.Now }()
+
+	manifest := BackupManifest(BackupOptions{Namespaces: []string{"shop", "billing"}, TTL: 72 * time.Hour})
+	for _, want := range []string{"kind: Backup", "name: shop-billing-20240101-1200", "namespace: velero", `- "shop"`, `- "billing"`, "ttl: 72h0m0s"} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("manifest missing %q:\n%s", want, manifest)
+		}
+	}
+
+	all := BackupManifest(PreUpgradeBackup("Prod_EU"))
+	for _, want := range []string{"name: pre-upgrade-prod-eu-20240101-1200", `- "*"`, "ttl: 720h0m0s"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("pre-upgrade manifest missing %q:\n%s", want, all)
+		}
+	}
+}
+
+func TestRestorePlan(t *testing.T) {
+	backupNow = func() time.Time { return time.Date(2024, 1, 3, 9, 30, 0, 0, time.UTC) }
+	defer func() { backupNow = time.Now }()
+
+	plan := RestorePlan(RestoreOptions{Backup: "shop-20240101-1200", Namespaces: []string{"shop"}, TargetNamespace: "shop-restored"})
+	manifest := plan.Steps[0].Manifest
+	for _, want := range []string{"kind: Restore", "name: shop-20240101-1200-restore-20240103-0930", "backupName: shop-20240101-1200", "namespaceMapping:", "shop: shop-restored"} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("manifest missing %q:\n%s", want, manifest)
+		}
+	}
+	if len(plan.Warnings) != 0 {
+		t.Errorf("restore into a new namespace warned: %v", plan.Warnings)
+	}
+
+	inPlace := RestorePlan(RestoreOptions{Backup: "shop-20240101-1200", Namespaces: []string{"shop"}})
+	if strings.Contains(inPlace.Steps[0].Manifest, "namespaceMapping") || len(inPlace.Warnings) == 0 {
+		t.Errorf("in-place restore = %+v", inPlace)
+	}
+}
+
+func TestInstallPlan(t *testing.T) {
+	plan := InstallPlan(InstallOptions{Provider: "aws", Bucket: "my-backups", Region: "eu-west-1"})
+	if len(plan.Steps) != 3 || len(plan.Warnings) != 0 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	args := strings.Join(plan.Steps[2].Args, " ")
+	for _, want := range []string{
+		"upgrade velero vmware-tanzu/velero --install -n velero --create-namespace",
+		"configuration.backupStorageLocation[0].bucket=my-backups",
+		"configuration.backupStorageLocation[0].config.region=eu-west-1",
+		"initContainers[0].image=velero/velero-plugin-for-aws:",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("install args missing %q: %s", want, args)
+		}
+	}
+
+	unknown := InstallPlan(InstallOptions{})
+	if !strings.Contains(strings.Join(unknown.Steps[2].Args, " "), "bucket=<BUCKET>") || len(unknown.Warnings) != 3 {
+		t.Errorf("plan without provider, bucket or region = %+v", unknown)
+	}
+
+	azure := InstallPlan(InstallOptions{Provider: "azure", Bucket: "velero"})
+	if !strings.Contains(strings.Join(azure.Steps[2].Args, " "), "velero-plugin-for-microsoft-azure") {
+		t.Errorf("azure plan = %+v", azure.Steps[2].Args)
+	}
+}
+
+func TestListBackups(t *testing.T) {
+	s := NewSubAgent(testClient(), false)
+	backups, err := s.ListBackups(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, b := range backups {
+		names = append(names, b.Name)
+	}
+	if got := strings.Join(names, ","); got != "nightly-20240102-0000,shop-20240101-1200,billing-20231201-1200" {
+		t.Errorf("order = %s", got)
+	}
+	if backups[1].Size != 1610612736 || backups[1].Items != 42 {
+		t.Errorf("shop backup = %+v", backups[1])
+	}
+
+	now := time.Date(2024, 1, 4, 12, 0, 0, 0, time.UTC)
+	table := FormatBackups(backups, now)
+	for _, want := range []string{"1.5Gi", "in 27d", "expired", "2 errors", "all"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("table missing %q:\n%s", want, table)
+		}
+	}
+
+	if latest, ok := LatestBackup(backups, []string{"shop"}); !ok || latest.Name != "shop-20240101-1200" {
+		t.Errorf("LatestBackup(shop) = %s, %v", latest.Name, ok)
+	}
+	if _, ok := LatestBackup(backups, []string{"payments"}); ok {
+		t.Error("LatestBackup found a backup of a namespace no completed backup covers")
+	}
+}
+
+func TestHandleQuery(t *testing.T) {
+	ctx := context.Background()
+
+	notInstalled := NewSubAgent(&mockK8sClient{}, false)
+	resp, err := notInstalled.HandleQuery(ctx, "back up namespace shop", QueryOptions{})
+	if err != nil || resp.Type != ResponseTypeResult || !strings.Contains(resp.Message, "not installed") {
+		t.Errorf("without velero = %+v, %v", resp, err)
+	}
+	resp, err = notInstalled.HandleQuery(ctx, "install velero with bucket my-backups", QueryOptions{Provider: "gcp"})
+	if err != nil || resp.Plan == nil || !strings.Contains(resp.Plan.Summary, "gcp bucket my-backups") {
+		t.Errorf("install = %+v, %v", resp, err)
+	}
+
+	s := NewSubAgent(testClient(), false)
+	resp, err = s.HandleQuery(ctx, "restore namespace shop from the latest backup into shop-copy", QueryOptions{})
+	if err != nil || resp.Plan == nil {
+		t.Fatalf("restore = %+v, %v", resp, err)
+	}
+	if !strings.Contains(resp.Plan.Steps[0].Manifest, "backupName: shop-20240101-1200") {
+		t.Errorf("restore did not pick the latest completed backup:\n%s", resp.Plan.Steps[0].Manifest)
+	}
+
+	resp, err = s.HandleQuery(ctx, "list velero backups", QueryOptions{})
+	if err != nil || resp.Type != ResponseTypeResult || !strings.Contains(resp.Data.(string), "shop-20240101-1200") {
+		t.Errorf("list = %+v, %v", resp, err)
+	}
+}
+
+func TestWaitForBackup(t *testing.T) {
+	backupPollInterval = time.Millisecond
+	defer func() { backupPollInterval = 10 * time.Second }()
+	ctx := context.Background()
+
+	client := &mockK8sClient{installed: true, jsonByResource: map[string]string{
+		"backups.velero.io": `{"metadata":{"name":"pre-upgrade"},"status":{"phase":"Completed"}}`,
+	}}
+	s := NewSubAgent(client, false)
+	if err := s.CreateBackup(ctx, PreUpgradeBackup("prod")); err != nil || len(client.applied) != 1 {
+		t.Fatalf("CreateBackup = %v, applied %d", err, len(client.applied))
+	}
+	if err := s.WaitForBackup(ctx, "pre-upgrade", time.Second); err != nil {
+		t.Errorf("completed backup: %v", err)
+	}
+
+	client.jsonByResource["backups.velero.io"] = `{"metadata":{"name":"pre-upgrade"},"status":{"phase":"PartiallyFailed","errors":3}}`
+	if err := s.WaitForBackup(ctx, "pre-upgrade", time.Second); err == nil || !strings.Contains(err.Error(), "PartiallyFailed") {
+		t.Errorf("failed backup: %v", err)
+	}
+
+	client.jsonByResource["backups.velero.io"] = `{"metadata":{"name":"pre-upgrade"},"status":{"phase":"InProgress"}}`
+	if err := s.WaitForBackup(ctx, "pre-upgrade", 5*time.Millisecond); err == nil || !strings.Contains(err.Error(), "did not complete") {
+		t.Errorf("slow backup: %v", err)
+	}
+}
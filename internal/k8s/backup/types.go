@@ -0,0 +1,121 @@
+package backup
+
+import (
+	"context"
+	"time"
+)
+
+// ResponseType indicates the type of response
+type ResponseType string
+
+const (
+	ResponseTypeResult ResponseType = "result"
+	ResponseTypePlan   ResponseType = "plan"
+	ResponseTypeError  ResponseType = "error"
+)
+
+// Action is what a backup request asks for
+type Action string
+
+const (
+	ActionInstall Action = "install" // install Velero with helm
+	ActionBackup  Action = "backup"  // back up namespaces
+	ActionRestore Action = "restore" // restore namespaces from a backup
+	ActionList    Action = "list"    // list backups with sizes and expiry
+)
+
+// K8sClient defines the interface for kubectl operations needed by backup
+type K8sClient interface {
+	Run(ctx context.Context, args ...string) (string, error)
+	RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error)
+	RunJSON(ctx context.Context, args ...string) ([]byte, error)
+	Apply(ctx context.Context, manifest string, namespace string) (string, error)
+}
+
+// QueryOptions contains options for backup queries
+type QueryOptions struct {
+	Namespace string
+	// Provider is the cluster's cloud (aws, gcp or azure), used to pick
+	// the object storage plugin when the request does not name one
+	Provider string
+}
+
+// Response from the backup sub-agent
+type Response struct {
+	Type    ResponseType
+	Data    interface{}
+	Plan    *BackupPlan
+	Message string
+	Error   error
+}
+
+// BackupPlan is a plan to install Velero, take a backup or restore one
+type BackupPlan struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"createdAt"`
+	Summary   string       `json:"summary"`
+	Steps     []BackupStep `json:"steps"`
+	Notes     []string     `json:"notes,omitempty"`
+	Warnings  []string     `json:"warnings,omitempty"`
+}
+
+// BackupStep is a single step in a backup plan
+type BackupStep struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	Command     string   `json:"command"`
+	Args        []string `json:"args"`
+	Manifest    string   `json:"manifest,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+}
+
+// Intent is a parsed backup request
+type Intent struct {
+	Action Action
+	// Backup names the backup to restore from; empty picks the latest
+	// completed backup of the namespaces
+	Backup string
+	// Namespaces to back up or restore; empty means every namespace
+	Namespaces []string
+	// TargetNamespace restores a single namespace under another name
+	TargetNamespace string
+	TTL             time.Duration
+	Provider        string // aws, gcp or azure
+	Bucket          string
+}
+
+// InstallOptions configures a Velero install
+type InstallOptions struct {
+	Provider string // aws, gcp or azure
+	Bucket   string
+	Region   string
+}
+
+// BackupOptions configures a Velero Backup
+type BackupOptions struct {
+	Name       string
+	Namespaces []string // empty backs up every namespace
+	TTL        time.Duration
+}
+
+// RestoreOptions configures a Velero Restore
+type RestoreOptions struct {
+	Name            string
+	Backup          string
+	Namespaces      []string
+	TargetNamespace string
+}
+
+// BackupInfo summarizes a Velero Backup
+type BackupInfo struct {
+	Name       string    `json:"name"`
+	Phase      string    `json:"phase"`
+	Namespaces []string  `json:"namespaces"`
+	Items      int       `json:"items"`
+	Size       int64     `json:"size"` // bytes of volume data uploaded by file system or data mover backups
+	Location   string    `json:"location,omitempty"`
+	Created    time.Time `json:"created"`
+	Expires    time.Time `json:"expires"`
+	Errors     int       `json:"errors,omitempty"`
+	Warnings   int       `json:"warnings,omitempty"`
+}
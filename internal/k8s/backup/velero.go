@@ -0,0 +1,530 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// VeleroNamespace is where the helm chart installs Velero and where
+	// Backup and Restore objects live
+	VeleroNamespace = "velero"
+
+	// DefaultTTL is how long Velero keeps a backup unless told otherwise
+	DefaultTTL = 720 * time.Hour
+
+	// DefaultBackupTimeout is how long a pre-upgrade backup may take
+	DefaultBackupTimeout = 30 * time.Minute
+
+	chartRepo    = "vmware-tanzu"
+	chartRepoURL = "https://vmware-tanzu.github.io/helm-charts"
+	chart        = chartRepo + "/velero"
+)
+
+// pluginImages are the object storage plugins for each provider
+var pluginImages = map[string]string{
+	"aws":   "velero/velero-plugin-for-aws:v1.10.0",
+	"gcp":   "velero/velero-plugin-for-gcp:v1.10.0",
+	"azure": "velero/velero-plugin-for-microsoft-azure:v1.10.0",
+}
+
+var (
+	// backupNow stamps backup and restore names; replaced in tests
+	backupNow = time.Now
+
+	// backupPollInterval is how often WaitForBackup checks the phase;
+	// replaced in tests
+	backupPollInterval = 10 * time.Second
+
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// InstallPlan installs Velero with its helm chart, storing backups in a
+// bucket of the cluster's cloud. Credentials are expected to come from the
+// cloud's workload identity (IRSA, GKE Workload Identity or Azure Workload
+// Identity) rather than a key in the cluster.
+func InstallPlan(opts InstallOptions) *BackupPlan {
+	plan := &BackupPlan{
+		Version:   1,
+		CreatedAt: time.Now(),
+		Summary:   "Install Velero with helm",
+	}
+
+	provider := opts.Provider
+	if _, ok := pluginImages[provider]; !ok {
+		plan.Warnings = append(plan.Warnings, "Cloud provider unknown; assuming AWS. Name the provider (aws, gcp or azure) to change it")
+		provider = "aws"
+	}
+	bucket := opts.Bucket
+	if bucket == "" {
+		bucket = "<BUCKET>"
+		plan.Warnings = append(plan.Warnings, "No bucket named; replace <BUCKET> or ask again with \"bucket <name>\"")
+	}
+	plan.Summary = fmt.Sprintf("Install Velero with helm, storing backups in %s bucket %s", provider, bucket)
+
+	location := "configuration.backupStorageLocation[0]."
+	snapshots := "configuration.volumeSnapshotLocation[0]."
+	set := []string{
+		location + "name=default",
+		location + "provider=" + provider,
+		location + "bucket=" + bucket,
+		snapshots + "name=default",
+		snapshots + "provider=" + provider,
+		"initContainers[0].name=velero-plugin-for-" + provider,
+		"initContainers[0].image=" + pluginImages[provider],
+		"initContainers[0].volumeMounts[0].mountPath=/target",
+		"initContainers[0].volumeMounts[0].name=plugins",
+		"credentials.useSecret=false",
+		"deployNodeAgent=true",
+	}
+	switch provider {
+	case "aws":
+		region := opts.Region
+		if region == "" {
+			region = "<REGION>"
+			plan.Warnings = append(plan.Warnings, "No region known; replace <REGION> with the bucket's region")
+		}
+		set = append(set, location+"config.region="+region, snapshots+"config.region="+region)
+	case "azure":
+		set = append(set,
+			location+"config.resourceGroup=<RESOURCE_GROUP>",
+			location+"config.storageAccount=<STORAGE_ACCOUNT>")
+		plan.Warnings = append(plan.Warnings, "Replace <RESOURCE_GROUP> and <STORAGE_ACCOUNT> with the storage account holding the container")
+	}
+
+	args := []string{"upgrade", "velero", chart, "--install", "-n", VeleroNamespace, "--create-namespace", "--wait"}
+	for _, value := range set {
+		args = append(args, "--set", value)
+	}
+
+	plan.Steps = []BackupStep{
+		{
+			ID:          "add-repo",
+			Description: "Add the vmware-tanzu helm repository",
+			Command:     "helm",
+			Args:        []string{"repo", "add", chartRepo, chartRepoURL},
+		},
+		{
+			ID:          "update-repo",
+			Description: "Update helm repositories",
+			Command:     "helm",
+			Args:        []string{"repo", "update"},
+		},
+		{
+			ID:          "install-velero",
+			Description: fmt.Sprintf("Install Velero in namespace %s", VeleroNamespace),
+			Command:     "helm",
+			Args:        args,
+			Reason:      "The node agent backs up volumes without a CSI snapshot class through file system backup",
+		},
+	}
+	plan.Notes = append(plan.Notes,
+		fmt.Sprintf("Grant the velero service account in %s write access to the bucket through workload identity before the first backup", VeleroNamespace),
+		fmt.Sprintf("Check the storage location with: kubectl get backupstoragelocations -n %s", VeleroNamespace))
+	return plan
+}
+
+// CreateBackupPlan takes a Velero backup of the namespaces
+func CreateBackupPlan(opts BackupOptions) *BackupPlan {
+	opts = opts.withDefaults()
+	scope := "every namespace"
+	if len(opts.Namespaces) > 0 {
+		scope = "namespace " + strings.Join(opts.Namespaces, ", ")
+	}
+	return &BackupPlan{
+		Version:   1,
+		CreatedAt: time.Now(),
+		Summary:   fmt.Sprintf("Back up %s with Velero as %s", scope, opts.Name),
+		Steps: []BackupStep{{
+			ID:          "backup-" + opts.Name,
+			Description: fmt.Sprintf("Create Velero backup %s of %s, kept for %s", opts.Name, scope, formatTTL(opts.TTL)),
+			Command:     "kubectl",
+			Args:        []string{"apply", "-f", "-"},
+			Manifest:    BackupManifest(opts),
+		}},
+		Notes: []string{
+			fmt.Sprintf("Follow progress with: kubectl get backups.velero.io %s -n %s -w", opts.Name, VeleroNamespace),
+		},
+	}
+}
+
+// RestorePlan restores namespaces from a Velero backup. Velero skips
+// objects that already exist, so a namespace is usually restored under
+// another name or after it has been deleted.
+func RestorePlan(opts RestoreOptions) *BackupPlan {
+	if opts.Name == "" {
+		opts.Name = dnsLabel(fmt.Sprintf("%s-restore-%s", opts.Backup, backupNow().UTC().Format("20060102-1504")))
+	}
+	scope := "every namespace"
+	if len(opts.Namespaces) > 0 {
+		scope = "namespace " + strings.Join(opts.Namespaces, ", ")
+	}
+	summary := fmt.Sprintf("Restore %s from Velero backup %s", scope, opts.Backup)
+	if opts.TargetNamespace != "" {
+		summary += " into " + opts.TargetNamespace
+	}
+
+	plan := &BackupPlan{
+		Version:   1,
+		CreatedAt: time.Now(),
+		Summary:   summary,
+		Steps: []BackupStep{{
+			ID:          "restore-" + opts.Backup,
+			Description: summary,
+			Command:     "kubectl",
+			Args:        []string{"apply", "-f", "-"},
+			Manifest:    RestoreManifest(opts),
+		}},
+		Notes: []string{
+			fmt.Sprintf("Follow progress with: kubectl get restores.velero.io %s -n %s -w", opts.Name, VeleroNamespace),
+		},
+	}
+	if opts.TargetNamespace == "" {
+		plan.Warnings = append(plan.Warnings, "Objects that still exist are skipped, not overwritten; delete them first or restore into another namespace")
+	}
+	return plan
+}
+
+// PreUpgradeBackup is the backup of every namespace taken before a cluster
+// upgrade
+func PreUpgradeBackup(clusterName string) BackupOptions {
+	return BackupOptions{
+		Name: dnsLabel(fmt.Sprintf("pre-upgrade-%s-%s", clusterName, backupNow().UTC().Format("20060102-1504"))),
+		TTL:  DefaultTTL,
+	}
+}
+
+func (o BackupOptions) withDefaults() BackupOptions {
+	if o.TTL == 0 {
+		o.TTL = DefaultTTL
+	}
+	if o.Name == "" {
+		prefix := "cluster"
+		if len(o.Namespaces) > 0 {
+			prefix = strings.Join(o.Namespaces, "-")
+		}
+		o.Name = dnsLabel(fmt.Sprintf("%s-%s", prefix, backupNow().UTC().Format("20060102-1504")))
+	}
+	return o
+}
+
+// BackupManifest returns the Velero Backup object for opts
+func BackupManifest(opts BackupOptions) string {
+	opts = opts.withDefaults()
+	namespaces := opts.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{"*"}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("apiVersion: velero.io/v1\n")
+	sb.WriteString("kind: Backup\n")
+	sb.WriteString("metadata:\n")
+	sb.WriteString(fmt.Sprintf("  name: %s\n", opts.Name))
+	sb.WriteString(fmt.Sprintf("  namespace: %s\n", VeleroNamespace))
+	sb.WriteString("spec:\n")
+	sb.WriteString("  includedNamespaces:\n")
+	for _, ns := range namespaces {
+		sb.WriteString(fmt.Sprintf("  - %q\n", ns))
+	}
+	sb.WriteString(fmt.Sprintf("  ttl: %s\n", opts.TTL))
+	return sb.String()
+}
+
+// RestoreManifest returns the Velero Restore object for opts
+func RestoreManifest(opts RestoreOptions) string {
+	var sb strings.Builder
+	sb.WriteString("apiVersion: velero.io/v1\n")
+	sb.WriteString("kind: Restore\n")
+	sb.WriteString("metadata:\n")
+	sb.WriteString(fmt.Sprintf("  name: %s\n", opts.Name))
+	sb.WriteString(fmt.Sprintf("  namespace: %s\n", VeleroNamespace))
+	sb.WriteString("spec:\n")
+	sb.WriteString(fmt.Sprintf("  backupName: %s\n", opts.Backup))
+	if len(opts.Namespaces) > 0 {
+		sb.WriteString("  includedNamespaces:\n")
+		for _, ns := range opts.Namespaces {
+			sb.WriteString(fmt.Sprintf("  - %s\n", ns))
+		}
+	}
+	if opts.TargetNamespace != "" && len(opts.Namespaces) == 1 {
+		sb.WriteString("  namespaceMapping:\n")
+		sb.WriteString(fmt.Sprintf("    %s: %s\n", opts.Namespaces[0], opts.TargetNamespace))
+	}
+	return sb.String()
+}
+
+// Installed reports whether the Velero CRDs are present
+func (s *SubAgent) Installed(ctx context.Context) bool {
+	_, err := s.client.Run(ctx, "get", "crd", "backups.velero.io", "-o", "name")
+	return err == nil
+}
+
+// CreateBackup applies the Backup object for opts
+func (s *SubAgent) CreateBackup(ctx context.Context, opts BackupOptions) error {
+	if _, err := s.client.Apply(ctx, BackupManifest(opts), VeleroNamespace); err != nil {
+		return fmt.Errorf("failed to create backup %s: %w", opts.Name, err)
+	}
+	return nil
+}
+
+// WaitForBackup waits until a backup completes, and fails as soon as
+// Velero marks it failed
+func (s *SubAgent) WaitForBackup(ctx context.Context, name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		output, err := s.client.RunJSON(ctx, "get", "backups.velero.io", name, "-n", VeleroNamespace)
+		if err == nil {
+			var b veleroBackup
+			if err := json.Unmarshal(output, &b); err != nil {
+				return fmt.Errorf("failed to parse backup %s: %w", name, err)
+			}
+			switch b.Status.Phase {
+			case "Completed":
+				return nil
+			case "Failed", "PartiallyFailed", "FailedValidation":
+				reason := b.Status.FailureReason
+				if reason == "" && len(b.Status.ValidationErrors) > 0 {
+					reason = strings.Join(b.Status.ValidationErrors, "; ")
+				}
+				if reason == "" {
+					reason = fmt.Sprintf("%d errors", b.Status.Errors)
+				}
+				return fmt.Errorf("backup %s %s: %s (see velero backup logs %s)", name, b.Status.Phase, reason, name)
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("backup %s did not complete within %s", name, timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backupPollInterval):
+		}
+	}
+}
+
+// veleroBackup is the part of a velero.io/v1 Backup that is read
+type veleroBackup struct {
+	Metadata struct {
+		Name              string    `json:"name"`
+		CreationTimestamp time.Time `json:"creationTimestamp"`
+	} `json:"metadata"`
+	Spec struct {
+		IncludedNamespaces []string `json:"includedNamespaces"`
+		StorageLocation    string   `json:"storageLocation"`
+	} `json:"spec"`
+	Status struct {
+		Phase            string    `json:"phase"`
+		Expiration       time.Time `json:"expiration"`
+		FailureReason    string    `json:"failureReason"`
+		ValidationErrors []string  `json:"validationErrors"`
+		Errors           int       `json:"errors"`
+		Warnings         int       `json:"warnings"`
+		Progress         struct {
+			ItemsBackedUp int `json:"itemsBackedUp"`
+		} `json:"progress"`
+	} `json:"status"`
+}
+
+// volumeUploads are PodVolumeBackups and DataUploads, the objects that
+// record how much volume data each backup sent to object storage
+type volumeUploads struct {
+	Items []struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Status struct {
+			Progress struct {
+				TotalBytes int64 `json:"totalBytes"`
+			} `json:"progress"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// ListBackups lists Velero backups, newest first. Size is the volume data
+// uploaded for the backup; Velero does not record the size of the
+// Kubernetes objects, so backups that only snapshot disks report none.
+func (s *SubAgent) ListBackups(ctx context.Context) ([]BackupInfo, error) {
+	output, err := s.client.RunJSON(ctx, "get", "backups.velero.io", "-n", VeleroNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list velero backups: %w", err)
+	}
+	var list struct {
+		Items []veleroBackup `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse velero backups: %w", err)
+	}
+
+	sizes := make(map[string]int64)
+	for _, resource := range []string{"podvolumebackups.velero.io", "datauploads.velero.io"} {
+		output, err := s.client.RunJSON(ctx, "get", resource, "-n", VeleroNamespace)
+		if err != nil {
+			// Older Velero versions have no DataUploads
+			logger.Debugf("skipping %s: %v", resource, err)
+			continue
+		}
+		var uploads volumeUploads
+		if err := json.Unmarshal(output, &uploads); err != nil {
+			continue
+		}
+		for _, u := range uploads.Items {
+			sizes[u.Metadata.Labels["velero.io/backup-name"]] += u.Status.Progress.TotalBytes
+		}
+	}
+
+	backups := make([]BackupInfo, 0, len(list.Items))
+	for _, b := range list.Items {
+		backups = append(backups, BackupInfo{
+			Name:       b.Metadata.Name,
+			Phase:      b.Status.Phase,
+			Namespaces: b.Spec.IncludedNamespaces,
+			Items:      b.Status.Progress.ItemsBackedUp,
+			Size:       sizes[b.Metadata.Name],
+			Location:   b.Spec.StorageLocation,
+			Created:    b.Metadata.CreationTimestamp,
+			Expires:    b.Status.Expiration,
+			Errors:     b.Status.Errors,
+			Warnings:   b.Status.Warnings,
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Created.After(backups[j].Created) })
+	return backups, nil
+}
+
+// LatestBackup returns the newest completed backup that includes every one
+// of namespaces
+func LatestBackup(backups []BackupInfo, namespaces []string) (BackupInfo, bool) {
+	for _, b := range backups {
+		if b.Phase == "Completed" && coversNamespaces(b, namespaces) {
+			return b, true
+		}
+	}
+	return BackupInfo{}, false
+}
+
+func coversNamespaces(b BackupInfo, namespaces []string) bool {
+	if len(b.Namespaces) == 0 {
+		return true
+	}
+	included := make(map[string]bool, len(b.Namespaces))
+	for _, ns := range b.Namespaces {
+		included[ns] = true
+	}
+	if included["*"] {
+		return true
+	}
+	for _, ns := range namespaces {
+		if !included[ns] {
+			return false
+		}
+	}
+	return len(namespaces) > 0
+}
+
+// FormatBackups renders backups as a table with their size and expiry
+func FormatBackups(backups []BackupInfo, now time.Time) string {
+	if len(backups) == 0 {
+		return "No Velero backups found"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Velero Backups:\n")
+	sb.WriteString(fmt.Sprintf("%-40s %-16s %-25s %-7s %-10s %-10s %s\n", "NAME", "STATUS", "NAMESPACES", "ITEMS", "SIZE", "AGE", "EXPIRES"))
+	for _, b := range backups {
+		namespaces := strings.Join(b.Namespaces, ",")
+		if namespaces == "" || namespaces == "*" {
+			namespaces = "all"
+		}
+		size := "-"
+		if b.Size > 0 {
+			size = formatBytes(b.Size)
+		}
+		phase := b.Phase
+		if phase == "" {
+			phase = "New"
+		}
+		sb.WriteString(fmt.Sprintf("%-40s %-16s %-25s %-7d %-10s %-10s %s\n",
+			b.Name, phase, namespaces, b.Items, size, formatAge(now.Sub(b.Created)), formatExpiry(b.Expires, now)))
+		if b.Errors > 0 || b.Warnings > 0 {
+			sb.WriteString(fmt.Sprintf("  %d errors, %d warnings (velero backup logs %s)\n", b.Errors, b.Warnings, b.Name))
+		}
+	}
+	return sb.String()
+}
+
+func formatExpiry(expires, now time.Time) string {
+	switch {
+	case expires.IsZero():
+		return "-"
+	case !expires.After(now):
+		return "expired"
+	default:
+		return "in " + formatAge(expires.Sub(now))
+	}
+}
+
+// formatAge rounds d to the largest whole unit, as kubectl does
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
+
+// formatTTL writes whole days as days
+func formatTTL(ttl time.Duration) string {
+	if ttl%(24*time.Hour) == 0 {
+		days := int(ttl / (24 * time.Hour))
+		if days == 1 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", days)
+	}
+	return ttl.String()
+}
+
+// formatBytes formats bytes to a readable string
+func formatBytes(b int64) string {
+	const (
+		Ki = 1024
+		Mi = Ki * 1024
+		Gi = Mi * 1024
+		Ti = Gi * 1024
+	)
+
+	switch {
+	case b >= Ti:
+		return fmt.Sprintf("%.1fTi", float64(b)/float64(Ti))
+	case b >= Gi:
+		return fmt.Sprintf("%.1fGi", float64(b)/float64(Gi))
+	case b >= Mi:
+		return fmt.Sprintf("%.1fMi", float64(b)/float64(Mi))
+	case b >= Ki:
+		return fmt.Sprintf("%.1fKi", float64(b)/float64(Ki))
+	default:
+		return fmt.Sprintf("%dB", b)
+	}
+}
+
+// dnsLabel makes s a valid object name
+func dnsLabel(s string) string {
+	s = strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	if len(s) > 63 {
+		s = strings.TrimRight(s[:63], "-")
+	}
+	return s
+}
@@ -0,0 +1,122 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// defaultReadCacheTTL is how long a read-only kubectl result is reused when
+// kubernetes.cache.ttl is unset. It covers one ask, which often lists pods,
+// services and events several times, without serving stale state to the next.
+const defaultReadCacheTTL = 15 * time.Second
+
+// cacheableVerbs are the kubectl verbs whose output is cached. Other
+// read-only verbs stream (logs -f, port-forward) or block (wait).
+var cacheableVerbs = map[string]bool{
+	"get": true, "describe": true, "top": true, "events": true, "explain": true,
+	"api-resources": true, "api-versions": true, "version": true, "cluster-info": true,
+}
+
+// readCache holds recent read-only kubectl output for the process, keyed by
+// the binary and the full argument list, which carries the kubeconfig,
+// context, impersonation and namespace. Any mutating call clears it.
+var readCache = struct {
+	sync.Mutex
+	entries map[string]*cachedRead
+}{entries: map[string]*cachedRead{}}
+
+// cachedRead is one kubectl result. done is closed once out and err are
+// set, so identical calls made while the first is running wait for it.
+type cachedRead struct {
+	done    chan struct{}
+	out     string
+	err     error
+	expires time.Time
+}
+
+// readCacheTTL is kubernetes.cache.ttl, or zero when --no-cache
+// (kubernetes.cache.disabled) is set
+func readCacheTTL() time.Duration {
+	if viper.GetBool("kubernetes.cache.disabled") {
+		return 0
+	}
+	if viper.IsSet("kubernetes.cache.ttl") {
+		return viper.GetDuration("kubernetes.cache.ttl")
+	}
+	return defaultReadCacheTTL
+}
+
+// cacheable reports whether kubectl args (verb first) only read and return
+// a snapshot that can be reused
+func cacheable(args []string) bool {
+	if len(args) == 0 || !cacheableVerbs[args[0]] {
+		return false
+	}
+	for _, arg := range args[1:] {
+		if arg == "-w" || strings.HasPrefix(arg, "--watch") {
+			return false
+		}
+	}
+	return true
+}
+
+// cachedRun returns the output of run for key, reusing a result younger
+// than the cache TTL. Failed calls are not kept.
+func cachedRun(ctx context.Context, key string, run func() (string, error)) (string, error) {
+	ttl := readCacheTTL()
+	if ttl <= 0 {
+		return run()
+	}
+
+	readCache.Lock()
+	entry, ok := readCache.entries[key]
+	if ok {
+		select {
+		case <-entry.done:
+			if time.Now().After(entry.expires) {
+				ok = false
+			}
+		default:
+		}
+	}
+	if ok {
+		readCache.Unlock()
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+		if entry.err != nil {
+			return run()
+		}
+		logger.Debugf("cache hit: %s", key)
+		return entry.out, nil
+	}
+	entry = &cachedRead{done: make(chan struct{})}
+	readCache.entries[key] = entry
+	readCache.Unlock()
+
+	entry.out, entry.err = run()
+	entry.expires = time.Now().Add(ttl)
+	close(entry.done)
+	if entry.err != nil {
+		readCache.Lock()
+		if readCache.entries[key] == entry {
+			delete(readCache.entries, key)
+		}
+		readCache.Unlock()
+	}
+	return entry.out, entry.err
+}
+
+// invalidateReads drops every cached result after a call that may have
+// changed the cluster
+func invalidateReads() {
+	readCache.Lock()
+	readCache.entries = map[string]*cachedRead{}
+	readCache.Unlock()
+}
@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// countingKubectl puts a kubectl on PATH that appends each call to a log
+// and returns the log's path
+func countingKubectl(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho \"$*\" >> " + calls + "\necho ok\n"
+	if err := os.WriteFile(filepath.Join(dir, "kubectl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	invalidateReads()
+	return calls
+}
+
+func callCount(t *testing.T, calls string) int {
+	t.Helper()
+	data, err := os.ReadFile(calls)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n")
+}
+
+func TestReadCache(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	calls := countingKubectl(t)
+	ctx := context.Background()
+	c := NewClient("", "prod", false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetPods(ctx, "web"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := callCount(t, calls); n != 1 {
+		t.Fatalf("kubectl ran %d times for one cached read", n)
+	}
+
+	// Another namespace or context is another key
+	if _, err := c.GetPods(ctx, "api"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient("", "staging", false).GetPods(ctx, "web"); err != nil {
+		t.Fatal(err)
+	}
+	if n := callCount(t, calls); n != 3 {
+		t.Fatalf("kubectl ran %d times, want 3", n)
+	}
+
+	// A change clears the cache
+	if _, err := c.Delete(ctx, "pod", "web-1", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetPods(ctx, "web"); err != nil {
+		t.Fatal(err)
+	}
+	if n := callCount(t, calls); n != 5 {
+		t.Fatalf("kubectl ran %d times after delete, want 5", n)
+	}
+}
+
+func TestReadCacheDisabled(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("kubernetes.cache.disabled", true)
+	calls := countingKubectl(t)
+	c := NewClient("", "prod", false)
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Get(context.Background(), "svc", "", "web"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := callCount(t, calls); n != 2 {
+		t.Errorf("kubectl ran %d times with --no-cache, want 2", n)
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	for _, tc := range []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"get", "pods", "-o", "json"}, true},
+		{[]string{"describe", "svc", "web"}, true},
+		{[]string{"get", "pods", "--watch"}, false},
+		{[]string{"logs", "web-1"}, false},
+		{[]string{"delete", "pod", "web-1"}, false},
+	} {
+		if got := cacheable(tc.args); got != tc.want {
+			t.Errorf("cacheable(%v) = %v, want %v", tc.args, got, tc.want)
+		}
+	}
+}
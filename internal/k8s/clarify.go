@@ -0,0 +1,53 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+)
+
+// Clarify returns o with answer filling the field a Clarification asked
+// about, for asking the query again
+func (o QueryOptions) Clarify(field, answer string) QueryOptions {
+	switch field {
+	case ClarifyContext:
+		o.Context = answer
+	case ClarifyResource:
+		o.ResourceNames = []string{answer}
+	}
+	return o
+}
+
+// resolveContext returns the kubeconfig context a query runs against. A
+// context in opts or a current context in the kubeconfig is used as is; a
+// kubeconfig with only one context uses it, and one with several returns a
+// clarification listing them. When kubectl cannot list contexts the query
+// goes ahead and reports kubectl's error.
+func (a *Agent) resolveContext(ctx context.Context, opts QueryOptions) (string, *Clarification) {
+	if opts.Context != "" {
+		return opts.Context, nil
+	}
+	if current, err := a.client.GetCurrentContext(ctx); err == nil && current != "" {
+		return "", nil
+	}
+	contexts, err := a.client.GetContexts(ctx)
+	if err != nil {
+		logger.Debugf("no current context and listing contexts failed: %v", err)
+		return "", nil
+	}
+	return contextClarification(contexts)
+}
+
+// contextClarification picks the only context, or asks which of several
+func contextClarification(contexts []string) (string, *Clarification) {
+	switch len(contexts) {
+	case 0:
+		return "", nil
+	case 1:
+		return contexts[0], nil
+	}
+	return "", &Clarification{
+		Question: fmt.Sprintf("No current kubectl context is set. Which of the %d clusters in your kubeconfig is this for?", len(contexts)),
+		Field:    ClarifyContext,
+		Options:  contexts,
+	}
+}
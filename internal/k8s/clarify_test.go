@@ -0,0 +1,29 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestContextClarification(t *testing.T) {
+	if got, c := contextClarification(nil); got != "" || c != nil {
+		t.Errorf("no contexts = %q, %+v", got, c)
+	}
+	if got, c := contextClarification([]string{"prod"}); got != "prod" || c != nil {
+		t.Errorf("one context = %q, %+v, want prod", got, c)
+	}
+	_, c := contextClarification([]string{"prod", "staging"})
+	if c == nil || c.Field != ClarifyContext || !reflect.DeepEqual(c.Options, []string{"prod", "staging"}) {
+		t.Errorf("two contexts = %+v, want a clarification", c)
+	}
+}
+
+func TestQueryOptionsClarify(t *testing.T) {
+	opts := QueryOptions{Namespace: "shop", ResourceNames: []string{"web"}}
+	if got := opts.Clarify(ClarifyContext, "prod"); got.Context != "prod" || got.Namespace != "shop" {
+		t.Errorf("Clarify(context) = %+v", got)
+	}
+	if got := opts.Clarify(ClarifyResource, "web-api"); !reflect.DeepEqual(got.ResourceNames, []string{"web-api"}) {
+		t.Errorf("Clarify(resource) = %+v", got)
+	}
+}
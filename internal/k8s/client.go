@@ -5,32 +5,117 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/dryrun"
+	"github.com/bgdnvk/clanker/internal/log"
+	"github.com/spf13/viper"
 )
 
+var logger = log.New("k8s")
+
 // Client provides kubectl command execution capabilities
 type Client struct {
 	kubeconfig string
 	context    string
 	namespace  string
 	debug      bool
+
+	// backend selects how supported operations reach the cluster; see
+	// SetBackend. kubectl remains the fallback for everything else.
+	backend    string
+	nativeOnce sync.Once
+	native     *nativeClient
+
+	// access replaces the kubeconfig with provider token exchange; see
+	// NewClientWithTokenAccess
+	access     *TokenAccess
+	accessOnce sync.Once
+	accessErr  error
+
+	// impersonate adds --as / --as-group to every call; see SetImpersonation
+	impersonate *Impersonation
+
+	// listOpts narrows list_pods operations; see SetListOptions
+	listOpts ListOptions
+
+	// binary is the kubectl-compatible CLI to exec; see SetBinary. It can
+	// switch to oc while other calls run, so binaryMu guards it.
+	binaryMu sync.RWMutex
+	binary   string
 }
 
-// NewClient creates a new K8s client
+// NewClient creates a new K8s client. The backend defaults to the
+// kubernetes.backend config value (kubectl when unset). Without an explicit
+// kubeconfig or context, a configured kubernetes.access section switches the
+// client to token exchange.
 func NewClient(kubeconfig, kubeContext string, debug bool) *Client {
-	return &Client{
+	c := &Client{
 		kubeconfig: kubeconfig,
 		context:    kubeContext,
 		namespace:  "default",
 		debug:      debug,
+		backend:    viper.GetString("kubernetes.backend"),
+		binary:     viper.GetString("kubernetes.binary"),
+	}
+	if kubeconfig == "" && kubeContext == "" {
+		// A bad access section surfaces on the first command
+		c.access, c.accessErr = AccessFromConfig()
+	}
+	return c
+}
+
+// NewClientWithTokenAccess creates a client that authenticates with
+// short-lived provider tokens (aws eks get-token, gcloud, az) instead of
+// ~/.kube/config. client-go calls use an in-memory REST config; kubectl and
+// helm get a token-free kubeconfig under ~/.clanker/kube that points at the
+// same exec plugin.
+func NewClientWithTokenAccess(access *TokenAccess, debug bool) *Client {
+	return &Client{
+		namespace: "default",
+		debug:     debug,
+		backend:   viper.GetString("kubernetes.backend"),
+		binary:    viper.GetString("kubernetes.binary"),
+		access:    access,
 	}
 }
 
+// TokenAccess returns the token exchange settings, or nil when the client
+// uses a kubeconfig
+func (c *Client) TokenAccess() *TokenAccess {
+	return c.access
+}
+
+// prepareAccess writes the token-exchange kubeconfig on first use and points
+// kubectl and helm at it
+func (c *Client) prepareAccess(ctx context.Context) error {
+	if c.access == nil {
+		return c.accessErr
+	}
+	c.accessOnce.Do(func() {
+		if c.accessErr != nil {
+			return
+		}
+		path, err := c.access.WriteKubeconfig(ctx)
+		if err != nil {
+			c.accessErr = fmt.Errorf("%s token access to %s failed: %w", c.access.Provider, c.access.Cluster, err)
+			return
+		}
+		c.kubeconfig = path
+	})
+	return c.accessErr
+}
+
 // BackendKubernetesCredentials represents Kubernetes credentials from the backend
 type BackendKubernetesCredentials struct {
 	KubeconfigContent string
@@ -73,6 +158,8 @@ func NewClientWithCredentials(creds *BackendKubernetesCredentials, debug bool) (
 		context:    creds.ContextName,
 		namespace:  "default",
 		debug:      debug,
+		backend:    viper.GetString("kubernetes.backend"),
+		binary:     viper.GetString("kubernetes.binary"),
 	}, tmpFile.Name(), nil
 }
 
@@ -108,9 +195,94 @@ func (c *Client) SetNamespace(namespace string) {
 	c.namespace = namespace
 }
 
+// SetListOptions sets the selectors and summary threshold applied to the
+// pod listings that ExecuteOperations runs, which only clanker k8s ask
+// uses; the Agent reads them from QueryOptions instead. The chunk size is
+// not one of them: kubernetes.chunk_size pages the get calls of every
+// client, on every path.
+func (c *Client) SetListOptions(opts ListOptions) {
+	c.listOpts = opts
+}
+
 // SetContext sets the kubectl context
 func (c *Client) SetContext(context string) {
 	c.context = context
+	c.nativeOnce = sync.Once{}
+	c.native = nil
+}
+
+// SetBackend selects the backend for get, delete, scale, and logs:
+// "kubectl" (default) or "client-go". Unsupported operations, and every
+// operation when client-go cannot be initialised, fall back to kubectl.
+func (c *Client) SetBackend(backend string) {
+	c.backend = backend
+}
+
+// Backend returns the configured backend name
+func (c *Client) Backend() string {
+	if strings.EqualFold(c.backend, BackendClientGo) {
+		return BackendClientGo
+	}
+	return BackendKubectl
+}
+
+// SetBinary selects the kubectl-compatible CLI the client execs, such as
+// "oc" on OpenShift. Empty restores kubectl.
+func (c *Client) SetBinary(binary string) {
+	c.binaryMu.Lock()
+	defer c.binaryMu.Unlock()
+	c.binary = binary
+}
+
+// Binary returns the CLI the client execs for kubectl commands
+func (c *Client) Binary() string {
+	c.binaryMu.RLock()
+	defer c.binaryMu.RUnlock()
+	if c.binary == "" {
+		return "kubectl"
+	}
+	return c.binary
+}
+
+// nativeBackend returns the client-go backend when it is selected and could
+// be initialised, or nil to use kubectl.
+func (c *Client) nativeBackend() *nativeClient {
+	if c.Backend() != BackendClientGo || c.impersonate != nil {
+		return nil
+	}
+	c.nativeOnce.Do(func() {
+		var native *nativeClient
+		var err error
+		if c.access != nil {
+			native, err = cachedNativeClientForAccess(context.Background(), c.access)
+		} else {
+			native, err = cachedNewNativeClient(c.kubeconfig, c.context)
+		}
+		if err != nil {
+			logger.Debugf("client-go backend unavailable, using kubectl: %v", err)
+			return
+		}
+		c.native = native
+	})
+	return c.native
+}
+
+// nativeNamespace resolves the namespace the same way buildArgs does
+func (c *Client) nativeNamespace(namespace string) string {
+	if namespace == "" {
+		return c.namespace
+	}
+	return namespace
+}
+
+// useKubectlFallback reports whether a native call should be retried via
+// kubectl because client-go does not handle the request.
+func (c *Client) useKubectlFallback(err error) bool {
+	if !errors.Is(err, errNativeUnsupported) {
+		return false
+	}
+	logger.Debugf("falling back to kubectl: %v", err)
+	return true
 }
 
 // Run executes a kubectl command and returns the output
@@ -120,13 +292,33 @@ func (c *Client) Run(ctx context.Context, args ...string) (string, error) {
 
 // RunWithNamespace executes a kubectl command in a specific namespace
 func (c *Client) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	if err := c.prepareAccess(ctx); err != nil {
+		return "", err
+	}
+	args = withChunkSize(args)
 	cmdArgs := c.buildArgs(namespace, args)
+	if dryrun.Skip(os.Stdout, "kubectl", cmdArgs) {
+		return "", nil
+	}
 
-	if c.debug {
-		fmt.Printf("[kubectl] %s\n", strings.Join(cmdArgs, " "))
+	binary := c.Binary()
+	if !cacheable(args) {
+		if audit.IsMutation("kubectl", cmdArgs) {
+			defer invalidateReads()
+		}
+		return c.runKubectl(ctx, binary, cmdArgs)
 	}
+	key := binary + " " + strings.Join(cmdArgs, " ")
+	return cachedRun(ctx, key, func() (string, error) {
+		return c.runKubectl(ctx, binary, cmdArgs)
+	})
+}
 
-	cmd := exec.CommandContext(ctx, "kubectl", cmdArgs...)
+// runKubectl execs binary with the built arguments
+func (c *Client) runKubectl(ctx context.Context, binary string, cmdArgs []string) (string, error) {
+	logger.Debugf("%s", strings.Join(cmdArgs, " "))
+
+	cmd := exec.CommandContext(ctx, binary, cmdArgs...)
 	cmd.Env = os.Environ()
 
 	var stdout, stderr bytes.Buffer
@@ -134,6 +326,7 @@ func (c *Client) RunWithNamespace(ctx context.Context, namespace string, args ..
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
+	audit.RecordStep("kubectl", cmdArgs, err)
 	if err != nil {
 		return "", fmt.Errorf("kubectl command failed: %w, stderr: %s", err, stderr.String())
 	}
@@ -168,17 +361,25 @@ func (c *Client) Apply(ctx context.Context, manifest string, namespace string) (
 // applyManifest is the shared implementation behind Apply / ApplyDryRunServer.
 // dryRunServer toggles --dry-run=server.
 func (c *Client) applyManifest(ctx context.Context, manifest, namespace string, dryRunServer bool) (string, error) {
+	if err := c.prepareAccess(ctx); err != nil {
+		return "", err
+	}
 	applyArgs := []string{"apply", "-f", "-"}
 	if dryRunServer {
 		applyArgs = append(applyArgs, "--dry-run=server")
 	}
 	cmdArgs := c.buildArgs(namespace, applyArgs)
+	if !dryRunServer && dryrun.Enabled() {
+		dryrun.PrintInput(os.Stdout, "kubectl", cmdArgs, fmt.Sprintf("manifest (%d bytes)", len(manifest)))
+		return "", nil
+	}
 
-	if c.debug {
-		fmt.Printf("[kubectl] apply manifest (%d bytes)\n", len(manifest))
+	logger.Debugf("apply manifest (%d bytes)", len(manifest))
+	if !dryRunServer {
+		defer invalidateReads()
 	}
 
-	cmd := exec.CommandContext(ctx, "kubectl", cmdArgs...)
+	cmd := exec.CommandContext(ctx, c.Binary(), cmdArgs...)
 	cmd.Env = os.Environ()
 	cmd.Stdin = strings.NewReader(manifest)
 
@@ -187,6 +388,7 @@ func (c *Client) applyManifest(ctx context.Context, manifest, namespace string,
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
+	audit.RecordStep("kubectl", cmdArgs, err)
 	if err != nil {
 		return "", fmt.Errorf("kubectl apply failed: %w, stderr: %s", err, stderr.String())
 	}
@@ -196,6 +398,17 @@ func (c *Client) applyManifest(ctx context.Context, manifest, namespace string,
 
 // Delete deletes resources matching the given args
 func (c *Client) Delete(ctx context.Context, resourceType, name, namespace string) (string, error) {
+	if dryrun.Skip(os.Stdout, "kubectl", c.buildArgs(namespace, []string{"delete", resourceType, name})) {
+		return "", nil
+	}
+	if native := c.nativeBackend(); native != nil {
+		out, err := native.Delete(ctx, resourceType, name, c.nativeNamespace(namespace))
+		invalidateReads()
+		if !c.useKubectlFallback(err) {
+			audit.RecordStep("kubectl", c.buildArgs(namespace, []string{"delete", resourceType, name}), err)
+			return out, err
+		}
+	}
 	args := []string{"delete", resourceType, name}
 	return c.RunWithNamespace(ctx, namespace, args...)
 }
@@ -211,6 +424,12 @@ func (c *Client) Get(ctx context.Context, resourceType, name, namespace string)
 
 // GetJSON retrieves a resource as JSON
 func (c *Client) GetJSON(ctx context.Context, resourceType, name, namespace string) ([]byte, error) {
+	if native := c.nativeBackend(); native != nil {
+		out, err := native.GetJSON(ctx, resourceType, name, c.nativeNamespace(namespace))
+		if !c.useKubectlFallback(err) {
+			return out, err
+		}
+	}
 	args := []string{"get", resourceType}
 	if name != "" {
 		args = append(args, name)
@@ -234,6 +453,13 @@ func (c *Client) Describe(ctx context.Context, resourceType, name, namespace str
 
 // Logs retrieves logs from a pod
 func (c *Client) Logs(ctx context.Context, podName, namespace string, opts LogOptions) (string, error) {
+	if native := c.nativeBackend(); native != nil {
+		out, err := native.Logs(ctx, podName, c.nativeNamespace(namespace), opts)
+		if !c.useKubectlFallback(err) {
+			return out, err
+		}
+	}
+
 	args := []string{"logs", podName}
 
 	if opts.Container != "" {
@@ -266,6 +492,17 @@ type LogOptions struct {
 
 // Scale scales a deployment or statefulset
 func (c *Client) Scale(ctx context.Context, resourceType, name, namespace string, replicas int) (string, error) {
+	if dryrun.Skip(os.Stdout, "kubectl", c.buildArgs(namespace, []string{"scale", resourceType, name, "--replicas", fmt.Sprintf("%d", replicas)})) {
+		return "", nil
+	}
+	if native := c.nativeBackend(); native != nil {
+		out, err := native.Scale(ctx, resourceType, name, c.nativeNamespace(namespace), replicas)
+		invalidateReads()
+		if !c.useKubectlFallback(err) {
+			audit.RecordStep("kubectl", c.buildArgs(namespace, []string{"scale", resourceType, name, "--replicas", fmt.Sprintf("%d", replicas)}), err)
+			return out, err
+		}
+	}
 	args := []string{"scale", resourceType, name, "--replicas", fmt.Sprintf("%d", replicas)}
 	return c.RunWithNamespace(ctx, namespace, args...)
 }
@@ -285,12 +522,15 @@ func (c *Client) Exec(ctx context.Context, podName, namespace string, command []
 
 // PortForward starts port forwarding to a pod
 func (c *Client) PortForward(ctx context.Context, podName, namespace string, localPort, remotePort int) (*exec.Cmd, error) {
+	if err := c.prepareAccess(ctx); err != nil {
+		return nil, err
+	}
 	args := c.buildArgs(namespace, []string{
 		"port-forward", podName,
 		fmt.Sprintf("%d:%d", localPort, remotePort),
 	})
 
-	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	cmd := exec.CommandContext(ctx, c.Binary(), args...)
 	cmd.Env = os.Environ()
 
 	if err := cmd.Start(); err != nil {
@@ -323,13 +563,16 @@ func (c *Client) PortForwardStream(ctx context.Context, namespace string, opts P
 		return nil, fmt.Errorf("PortForwardStream: PortSpec is required")
 	}
 
+	if err := c.prepareAccess(ctx); err != nil {
+		return nil, err
+	}
 	pfArgs := []string{"port-forward", opts.Target, opts.PortSpec}
 	if opts.Addresses != "" {
 		pfArgs = append(pfArgs, "--address", opts.Addresses)
 	}
 	args := c.buildArgs(namespace, pfArgs)
 
-	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	cmd := exec.CommandContext(ctx, c.Binary(), args...)
 	cmd.Env = os.Environ()
 	if opts.Stdout != nil {
 		cmd.Stdout = opts.Stdout
@@ -559,6 +802,67 @@ func (c *Client) CheckConnection(ctx context.Context) error {
 	return err
 }
 
+// withChunkSize adds --chunk-size to get calls when kubernetes.chunk_size
+// (--chunk-size) is set, so kubectl pages large lists from the API server
+// in requests of that many items
+func withChunkSize(args []string) []string {
+	size := viper.GetInt("kubernetes.chunk_size")
+	if size <= 0 || len(args) == 0 || args[0] != "get" {
+		return args
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--chunk-size") {
+			return args
+		}
+	}
+	return append(append([]string(nil), args...), fmt.Sprintf("--chunk-size=%d", size))
+}
+
+// defaultPodPageSize is the page size pod listings use when
+// kubernetes.chunk_size is not set, the same as kubectl's default
+const defaultPodPageSize = 500
+
+// pagePods lists pods kubernetes.chunk_size at a time with the API's limit
+// and continue parameters and passes each page to fn as it arrives.
+// kubectl get --chunk-size would gather every page before printing any.
+func (c *Client) pagePods(ctx context.Context, namespace, labelSelector, fieldSelector string, fn func(page []ClusterPodInfo)) error {
+	if err := c.prepareAccess(ctx); err != nil {
+		return err
+	}
+	size := viper.GetInt("kubernetes.chunk_size")
+	if size <= 0 {
+		size = defaultPodPageSize
+	}
+	path := "/api/v1/pods"
+	if namespace != "" {
+		path = "/api/v1/namespaces/" + url.PathEscape(namespace) + "/pods"
+	}
+	query := url.Values{"limit": {strconv.Itoa(size)}}
+	if labelSelector != "" {
+		query.Set("labelSelector", labelSelector)
+	}
+	if fieldSelector != "" {
+		query.Set("fieldSelector", fieldSelector)
+	}
+
+	// Pages skip the read cache, which would keep every one of them
+	for {
+		output, err := c.runKubectl(ctx, c.Binary(), c.buildArgs("", []string{"get", "--raw", path + "?" + query.Encode()}))
+		if err != nil {
+			return err
+		}
+		pods, next, err := parsePodList([]byte(output))
+		if err != nil {
+			return err
+		}
+		fn(pods)
+		if next == "" {
+			return nil
+		}
+		query.Set("continue", next)
+	}
+}
+
 // buildArgs builds the kubectl command arguments
 func (c *Client) buildArgs(namespace string, args []string) []string {
 	cmdArgs := make([]string, 0, len(args)+6)
@@ -567,10 +871,15 @@ func (c *Client) buildArgs(namespace string, args []string) []string {
 		cmdArgs = append(cmdArgs, "--kubeconfig", c.kubeconfig)
 	}
 
-	if c.context != "" {
+	// The token-exchange kubeconfig has a single current context
+	if c.context != "" && c.access == nil {
 		cmdArgs = append(cmdArgs, "--context", c.context)
 	}
 
+	if c.impersonate != nil {
+		cmdArgs = append(cmdArgs, c.impersonate.KubectlFlags()...)
+	}
+
 	// Use specified namespace or default
 	ns := namespace
 	if ns == "" {
@@ -612,12 +921,19 @@ func (c *Client) RunHelm(ctx context.Context, args ...string) (string, error) {
 
 // RunHelmWithNamespace executes a helm command in a specific namespace
 func (c *Client) RunHelmWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	if err := c.prepareAccess(ctx); err != nil {
+		return "", err
+	}
 	cmdArgs := c.buildHelmArgs(namespace, args)
-
-	if c.debug {
-		fmt.Printf("[helm] %s\n", strings.Join(cmdArgs, " "))
+	if dryrun.Skip(os.Stdout, "helm", cmdArgs) {
+		return "", nil
+	}
+	if audit.IsMutation("helm", cmdArgs) {
+		defer invalidateReads()
 	}
 
+	logger.Debugf("%s", strings.Join(cmdArgs, " "))
+
 	cmd := exec.CommandContext(ctx, "helm", cmdArgs...)
 	cmd.Env = os.Environ()
 
@@ -626,6 +942,7 @@ func (c *Client) RunHelmWithNamespace(ctx context.Context, namespace string, arg
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
+	audit.RecordStep("helm", cmdArgs, err)
 	if err != nil {
 		return "", fmt.Errorf("helm command failed: %w, stderr: %s", err, stderr.String())
 	}
@@ -641,10 +958,14 @@ func (c *Client) buildHelmArgs(namespace string, args []string) []string {
 		cmdArgs = append(cmdArgs, "--kubeconfig", c.kubeconfig)
 	}
 
-	if c.context != "" {
+	if c.context != "" && c.access == nil {
 		cmdArgs = append(cmdArgs, "--kube-context", c.context)
 	}
 
+	if c.impersonate != nil {
+		cmdArgs = append(cmdArgs, c.impersonate.HelmFlags()...)
+	}
+
 	// Add namespace if specified
 	ns := namespace
 	if ns == "" {
@@ -5,6 +5,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/spf13/viper"
 )
 
 func TestNewClient(t *testing.T) {
@@ -559,3 +561,26 @@ func TestIsHelmAvailable(t *testing.T) {
 	// We just verify the function doesn't panic
 	_ = IsHelmAvailable()
 }
+
+func TestWithChunkSize(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	args := []string{"get", "pods", "-A", "-o", "json"}
+	if got := withChunkSize(args); strings.Join(got, " ") != "get pods -A -o json" {
+		t.Errorf("unset chunk size changed args: %v", got)
+	}
+
+	viper.Set("kubernetes.chunk_size", 250)
+	if got := withChunkSize(args); strings.Join(got, " ") != "get pods -A -o json --chunk-size=250" {
+		t.Errorf("args = %v", got)
+	}
+	if len(args) != 5 {
+		t.Errorf("caller's args modified: %v", args)
+	}
+	for _, keep := range [][]string{{"describe", "pod", "web-1"}, {"get", "pods", "--chunk-size=0"}} {
+		if got := withChunkSize(keep); len(got) != len(keep) {
+			t.Errorf("withChunkSize(%v) = %v", keep, got)
+		}
+	}
+}
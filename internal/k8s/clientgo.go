@@ -0,0 +1,278 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Backend names accepted by the kubernetes.backend config key
+const (
+	BackendKubectl  = "kubectl"
+	BackendClientGo = "client-go"
+)
+
+// errNativeUnsupported marks requests the client-go backend does not handle,
+// so the caller falls back to kubectl.
+var errNativeUnsupported = errors.New("not supported by client-go backend")
+
+// nativeClient talks to the API server directly with client-go. It covers the
+// hot read/write paths (get, delete, scale, logs); everything else stays on
+// kubectl.
+type nativeClient struct {
+	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
+	mapper    meta.RESTMapper
+	namespace string // kubeconfig context namespace
+}
+
+// newNativeClient builds a client-go backend from the same kubeconfig and
+// context kubectl would use.
+// nativeClientTTL bounds how long a cached client (and its discovery data)
+// is reused, so new CRDs show up without restarting a warm daemon.
+const nativeClientTTL = 10 * time.Minute
+
+var nativeClientCache = struct {
+	sync.Mutex
+	entries map[string]cachedNativeClient
+}{entries: map[string]cachedNativeClient{}}
+
+type cachedNativeClient struct {
+	client  *nativeClient
+	created time.Time
+}
+
+// cachedNewNativeClient returns a process-wide client for the kubeconfig and
+// context, building one on first use. Discovery is the slow part of native
+// calls; sharing the client keeps it warm across commands in the daemon.
+func cachedNewNativeClient(kubeconfig, kubeContext string) (*nativeClient, error) {
+	return cachedNativeClientFor(kubeconfig+"|"+kubeContext, func() (*nativeClient, error) {
+		return newNativeClient(kubeconfig, kubeContext)
+	})
+}
+
+// cachedNativeClientForAccess is cachedNewNativeClient for token-exchange
+// access; the REST config is built in memory, never from a kubeconfig.
+func cachedNativeClientForAccess(ctx context.Context, access *TokenAccess) (*nativeClient, error) {
+	return cachedNativeClientFor("access|"+access.key(), func() (*nativeClient, error) {
+		restConfig, err := access.RESTConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return newNativeClientForConfig(restConfig, "default")
+	})
+}
+
+func cachedNativeClientFor(key string, build func() (*nativeClient, error)) (*nativeClient, error) {
+	nativeClientCache.Lock()
+	defer nativeClientCache.Unlock()
+	if entry, ok := nativeClientCache.entries[key]; ok && time.Since(entry.created) < nativeClientTTL {
+		return entry.client, nil
+	}
+	client, err := build()
+	if err != nil {
+		return nil, err
+	}
+	nativeClientCache.entries[key] = cachedNativeClient{client: client, created: time.Now()}
+	return client, nil
+}
+
+func newNativeClient(kubeconfig, kubeContext string) (*nativeClient, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		rules.ExplicitPath = kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	restConfig, err := loader.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	namespace, _, err := loader.Namespace()
+	if err != nil || namespace == "" {
+		namespace = "default"
+	}
+	return newNativeClientForConfig(restConfig, namespace)
+}
+
+func newNativeClientForConfig(restConfig *rest.Config, namespace string) (*nativeClient, error) {
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+	dyn, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	disc := memory.NewMemCacheClient(clientset.Discovery())
+	mapper := restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(disc), disc, nil)
+
+	return &nativeClient{
+		clientset: clientset,
+		dynamic:   dyn,
+		mapper:    mapper,
+		namespace: namespace,
+	}, nil
+}
+
+// resolve maps a kubectl-style resource argument (pods, deploy,
+// deployments.apps) to its REST mapping.
+func (n *nativeClient) resolve(resourceType string) (*meta.RESTMapping, error) {
+	resourceType = strings.ToLower(strings.TrimSpace(resourceType))
+	if resourceType == "" || resourceType == "all" || strings.ContainsAny(resourceType, ",/") {
+		return nil, errNativeUnsupported
+	}
+
+	gvr, gr := schema.ParseResourceArg(resourceType)
+	if gvr == nil {
+		g := gr.WithVersion("")
+		gvr = &g
+	}
+	full, err := n.mapper.ResourceFor(*gvr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNativeUnsupported, err)
+	}
+	gvk, err := n.mapper.KindFor(full)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errNativeUnsupported, err)
+	}
+	return n.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+func (n *nativeClient) resourceFor(mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return n.dynamic.Resource(mapping.Resource)
+	}
+	if namespace == "" || namespace == "all" {
+		namespace = n.namespace
+	}
+	return n.dynamic.Resource(mapping.Resource).Namespace(namespace)
+}
+
+// GetJSON mirrors `kubectl get <type> [name] -o json`. Lists are wrapped in a
+// kind: List envelope with apiVersion/kind set on each item, as kubectl does.
+func (n *nativeClient) GetJSON(ctx context.Context, resourceType, name, namespace string) ([]byte, error) {
+	mapping, err := n.resolve(resourceType)
+	if err != nil {
+		return nil, err
+	}
+	ri := n.resourceFor(mapping, namespace)
+
+	if name != "" {
+		obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(obj.Object, "", "    ")
+	}
+
+	list, err := ri.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]interface{}, 0, len(list.Items))
+	for i := range list.Items {
+		item := list.Items[i]
+		if item.GetKind() == "" {
+			item.SetAPIVersion(mapping.GroupVersionKind.GroupVersion().String())
+			item.SetKind(mapping.GroupVersionKind.Kind)
+		}
+		items = append(items, item.Object)
+	}
+	return json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      items,
+		"metadata":   map[string]interface{}{"resourceVersion": ""},
+	}, "", "    ")
+}
+
+// Delete mirrors `kubectl delete <type> <name>`, including its output line.
+func (n *nativeClient) Delete(ctx context.Context, resourceType, name, namespace string) (string, error) {
+	if name == "" {
+		return "", errNativeUnsupported
+	}
+	mapping, err := n.resolve(resourceType)
+	if err != nil {
+		return "", err
+	}
+	policy := metav1.DeletePropagationBackground
+	if err := n.resourceFor(mapping, namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &policy}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %q deleted\n", kubectlResourceRef(mapping), name), nil
+}
+
+// Scale mirrors `kubectl scale <type> <name> --replicas N`.
+func (n *nativeClient) Scale(ctx context.Context, resourceType, name, namespace string, replicas int) (string, error) {
+	mapping, err := n.resolve(resourceType)
+	if err != nil {
+		return "", err
+	}
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	if _, err := n.resourceFor(mapping, namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s scaled\n", kubectlResourceRef(mapping), name), nil
+}
+
+// Logs mirrors `kubectl logs`. Follow mode stays on kubectl.
+func (n *nativeClient) Logs(ctx context.Context, podName, namespace string, opts LogOptions) (string, error) {
+	if opts.Follow || strings.Contains(podName, "/") {
+		return "", errNativeUnsupported
+	}
+	if namespace == "" || namespace == "all" {
+		namespace = n.namespace
+	}
+
+	logOpts := &corev1.PodLogOptions{
+		Container: opts.Container,
+		Previous:  opts.Previous,
+	}
+	if opts.TailLines > 0 {
+		tail := int64(opts.TailLines)
+		logOpts.TailLines = &tail
+	}
+	if opts.Since != "" {
+		since, err := time.ParseDuration(opts.Since)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", errNativeUnsupported, err)
+		}
+		seconds := int64(since.Seconds())
+		logOpts.SinceSeconds = &seconds
+	}
+
+	raw, err := n.clientset.CoreV1().Pods(namespace).GetLogs(podName, logOpts).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+// kubectlResourceRef formats a resource the way kubectl prints it in
+// mutation output: "deployment.apps" or "pod" for the core group.
+func kubectlResourceRef(mapping *meta.RESTMapping) string {
+	kind := strings.ToLower(mapping.GroupVersionKind.Kind)
+	if group := mapping.GroupVersionKind.Group; group != "" {
+		return kind + "." + group
+	}
+	return kind
+}
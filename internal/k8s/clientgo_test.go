@@ -0,0 +1,190 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func newFakeNativeClient(t *testing.T, objects ...runtime.Object) *nativeClient {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	return &nativeClient{
+		clientset: kubefake.NewClientset(),
+		dynamic:   dynamicfake.NewSimpleDynamicClient(scheme, objects...),
+		mapper:    mapper,
+		namespace: "default",
+	}
+}
+
+func fakeDeployment(name, namespace string, replicas int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+}
+
+// kubectlList is the envelope `kubectl get <type> -o json` prints for lists
+type kubectlList struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Items      []struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	} `json:"items"`
+}
+
+func TestNativeGetJSONListMatchesKubectlShape(t *testing.T) {
+	native := newFakeNativeClient(t,
+		fakeDeployment("api", "prod", 2),
+		fakeDeployment("worker", "prod", 1),
+		fakeDeployment("other", "staging", 1),
+	)
+
+	for _, resourceType := range []string{"deployments", "deployment", "deployments.apps"} {
+		raw, err := native.GetJSON(context.Background(), resourceType, "", "prod")
+		if err != nil {
+			t.Fatalf("GetJSON(%q) error: %v", resourceType, err)
+		}
+		var list kubectlList
+		if err := json.Unmarshal(raw, &list); err != nil {
+			t.Fatalf("GetJSON(%q) returned invalid JSON: %v", resourceType, err)
+		}
+		if list.APIVersion != "v1" || list.Kind != "List" {
+			t.Fatalf("envelope = %s/%s, want v1/List", list.APIVersion, list.Kind)
+		}
+		if len(list.Items) != 2 {
+			t.Fatalf("GetJSON(%q) returned %d items, want 2", resourceType, len(list.Items))
+		}
+		for _, item := range list.Items {
+			if item.APIVersion != "apps/v1" || item.Kind != "Deployment" || item.Metadata.Namespace != "prod" {
+				t.Errorf("item %+v missing kubectl apiVersion/kind/namespace", item)
+			}
+		}
+	}
+}
+
+func TestNativeGetJSONSingleObjectAndNotFound(t *testing.T) {
+	native := newFakeNativeClient(t, fakeDeployment("api", "prod", 3))
+
+	raw, err := native.GetJSON(context.Background(), "deployment", "api", "prod")
+	if err != nil {
+		t.Fatalf("GetJSON error: %v", err)
+	}
+	var obj struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Replicas int `json:"replicas"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj.Kind != "Deployment" || obj.Spec.Replicas != 3 {
+		t.Fatalf("got kind=%q replicas=%d, want Deployment/3", obj.Kind, obj.Spec.Replicas)
+	}
+
+	// Callers match on "not found" the same way they do for kubectl stderr.
+	_, err = native.GetJSON(context.Background(), "deployment", "missing", "prod")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestNativeMutationOutputMatchesKubectl(t *testing.T) {
+	native := newFakeNativeClient(t, fakeDeployment("api", "prod", 1))
+	ctx := context.Background()
+
+	out, err := native.Scale(ctx, "deployment", "api", "prod", 4)
+	if err != nil {
+		t.Fatalf("Scale error: %v", err)
+	}
+	if out != "deployment.apps/api scaled\n" {
+		t.Errorf("Scale output = %q", out)
+	}
+	raw, err := native.GetJSON(ctx, "deployment", "api", "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var obj struct {
+		Spec struct {
+			Replicas int `json:"replicas"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj.Spec.Replicas != 4 {
+		t.Errorf("replicas after scale = %d, want 4", obj.Spec.Replicas)
+	}
+
+	out, err = native.Delete(ctx, "deployment", "api", "prod")
+	if err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if out != "deployment.apps \"api\" deleted\n" {
+		t.Errorf("Delete output = %q", out)
+	}
+}
+
+func TestNativeUnsupportedFallsBack(t *testing.T) {
+	native := newFakeNativeClient(t)
+	ctx := context.Background()
+
+	cases := []func() error{
+		func() error { _, err := native.GetJSON(ctx, "pods,svc", "", "default"); return err },
+		func() error { _, err := native.GetJSON(ctx, "all", "", "default"); return err },
+		func() error { _, err := native.GetJSON(ctx, "widgets", "", "default"); return err },
+		func() error { _, err := native.Logs(ctx, "api", "default", LogOptions{Follow: true}); return err },
+		func() error { _, err := native.Delete(ctx, "pods", "", "default"); return err },
+	}
+	for i, call := range cases {
+		if err := call(); !errors.Is(err, errNativeUnsupported) {
+			t.Errorf("case %d: expected errNativeUnsupported, got %v", i, err)
+		}
+	}
+}
+
+func TestClientBackendSelection(t *testing.T) {
+	c := NewClient("", "", false)
+	if c.Backend() != BackendKubectl {
+		t.Fatalf("default backend = %q, want kubectl", c.Backend())
+	}
+	if c.nativeBackend() != nil {
+		t.Fatal("kubectl backend should not initialise client-go")
+	}
+
+	c.SetBackend("Client-Go")
+	if c.Backend() != BackendClientGo {
+		t.Fatalf("backend = %q, want client-go", c.Backend())
+	}
+}
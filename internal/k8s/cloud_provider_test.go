@@ -68,6 +68,16 @@ func TestDetectCloudProviderFromContext(t *testing.T) {
 			contextName: "kind-my-cluster",
 			want:        CloudProviderUnknown,
 		},
+		{
+			name:        "oc login context",
+			contextName: "default/api-ocp-example-com:6443/kube:admin",
+			want:        CloudProviderOpenShift,
+		},
+		{
+			name:        "oc login context on AWS",
+			contextName: "shop/api-prod-aws-example-com:6443/alice",
+			want:        CloudProviderOpenShift,
+		},
 	}
 
 	for _, tt := range tests {
@@ -189,6 +199,16 @@ func TestDetectCloudProviderFromQuery(t *testing.T) {
 			query: "setup IRSA for pod",
 			want:  CloudProviderAWS,
 		},
+		{
+			name:  "OpenShift mention",
+			query: "list deploymentconfigs on openshift",
+			want:  CloudProviderOpenShift,
+		},
+		{
+			name:  "ROSA query mentioning AWS",
+			query: "check the scc for pods on my aws openshift cluster",
+			want:  CloudProviderOpenShift,
+		},
 		{
 			name:  "Generic query",
 			query: "show all pods in default namespace",
@@ -396,4 +416,8 @@ func TestCloudProviderConstants(t *testing.T) {
 	if CloudProviderAzure != "azure" {
 		t.Errorf("CloudProviderAzure should be 'azure', got %q", CloudProviderAzure)
 	}
+
+	if CloudProviderOpenShift != "openshift" {
+		t.Errorf("CloudProviderOpenShift should be 'openshift', got %q", CloudProviderOpenShift)
+	}
 }
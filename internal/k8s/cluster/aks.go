@@ -10,6 +10,9 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/dryrun"
 )
 
 // AKSProvider manages Azure Kubernetes Service clusters
@@ -123,9 +126,7 @@ func (p *AKSProvider) Create(ctx context.Context, opts CreateOptions) (*ClusterI
 		args = append(args, "--tags", strings.Join(tags, " "))
 	}
 
-	if p.debug {
-		fmt.Printf("[aks] creating cluster: az %s\n", strings.Join(args, " "))
-	}
+	aksLog.Debugf("creating cluster: az %s", strings.Join(args, " "))
 
 	_, err := p.runAzureCLI(ctx, subscription, args...)
 	if err != nil {
@@ -159,9 +160,7 @@ func (p *AKSProvider) Delete(ctx context.Context, clusterName string) error {
 		"--no-wait",
 	}
 
-	if p.debug {
-		fmt.Printf("[aks] deleting cluster: az %s\n", strings.Join(args, " "))
-	}
+	aksLog.Debugf("deleting cluster: az %s", strings.Join(args, " "))
 
 	_, err := p.runAzureCLI(ctx, p.subscriptionID, args...)
 	if err != nil {
@@ -207,14 +206,117 @@ func (p *AKSProvider) Scale(ctx context.Context, clusterName string, opts ScaleO
 		}
 	}
 
-	if p.debug {
-		fmt.Printf("[aks] scaling cluster: az %s\n", strings.Join(args, " "))
-	}
+	aksLog.Debugf("scaling cluster: az %s", strings.Join(args, " "))
 
 	_, err := p.runAzureCLI(ctx, p.subscriptionID, args...)
 	return err
 }
 
+// Upgrade moves the control plane to targetVersion, then upgrades each node
+// pool. AKS surge-upgrades pool nodes and az waits for each operation.
+func (p *AKSProvider) Upgrade(ctx context.Context, clusterName, targetVersion string) error {
+	if clusterName == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+
+	resourceGroup := p.resourceGroup
+	if resourceGroup == "" {
+		return &ErrInvalidConfiguration{Message: "resource group is required for upgrade"}
+	}
+
+	target, err := KubernetesMinor(targetVersion)
+	if err != nil {
+		return &ErrInvalidConfiguration{Message: err.Error()}
+	}
+
+	cluster, err := p.describeCluster(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if err := CheckUpgradePath(cluster.KubernetesVersion, target); err != nil {
+		return err
+	}
+
+	if !sameMinor(cluster.KubernetesVersion, target) {
+		// A minor version makes AKS pick the latest supported patch
+		args := []string{
+			"aks", "upgrade",
+			"--name", clusterName,
+			"--resource-group", resourceGroup,
+			"--kubernetes-version", target,
+			"--control-plane-only",
+			"--yes",
+		}
+		aksLog.Debugf("upgrading control plane: az %s", strings.Join(args, " "))
+		if _, err := p.runAzureCLI(ctx, p.subscriptionID, args...); err != nil {
+			return fmt.Errorf("control plane upgrade failed: %w", err)
+		}
+	}
+
+	nodePools, err := p.listNodePools(ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to list node pools: %w", err)
+	}
+
+	for _, np := range nodePools {
+		if sameMinor(np.OrchestratorVersion, target) {
+			continue
+		}
+		args := []string{
+			"aks", "nodepool", "upgrade",
+			"--name", np.Name,
+			"--cluster-name", clusterName,
+			"--resource-group", resourceGroup,
+			"--kubernetes-version", target,
+			"--yes",
+		}
+		aksLog.Debugf("upgrading node pool: az %s", strings.Join(args, " "))
+		if _, err := p.runAzureCLI(ctx, p.subscriptionID, args...); err != nil {
+			return fmt.Errorf("upgrade of node pool %s failed: %w", np.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// PatchNodes moves each node pool onto the latest node image, one pool at a
+// time, without changing its Kubernetes version. AKS surge-replaces the
+// pool's nodes and az waits for each operation.
+func (p *AKSProvider) PatchNodes(ctx context.Context, clusterName string, opts PatchOptions) error {
+	if clusterName == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+
+	resourceGroup := p.resourceGroup
+	if resourceGroup == "" {
+		return &ErrInvalidConfiguration{Message: "resource group is required for node patching"}
+	}
+
+	nodePools, err := p.listNodePools(ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to list node pools: %w", err)
+	}
+
+	for i, np := range nodePools {
+		opts.progress("aks", "moving node pool %s onto the latest node image (%d/%d)...", np.Name, i+1, len(nodePools))
+		args := []string{
+			"aks", "nodepool", "upgrade",
+			"--name", np.Name,
+			"--cluster-name", clusterName,
+			"--resource-group", resourceGroup,
+			"--node-image-only",
+			"--yes",
+		}
+		aksLog.Debugf("patching node pool: az %s", strings.Join(args, " "))
+		if _, err := p.runAzureCLI(ctx, p.subscriptionID, args...); err != nil {
+			return fmt.Errorf("node image update of node pool %s failed: %w", np.Name, err)
+		}
+		opts.progress("aks", "node pool %s is on the latest node image", np.Name)
+	}
+
+	return nil
+}
+
 // GetKubeconfig retrieves and updates kubeconfig for the cluster
 func (p *AKSProvider) GetKubeconfig(ctx context.Context, clusterName string) (string, error) {
 	if clusterName == "" {
@@ -248,9 +350,7 @@ func (p *AKSProvider) GetKubeconfig(ctx context.Context, clusterName string) (st
 		"--overwrite-existing",
 	}
 
-	if p.debug {
-		fmt.Printf("[aks] updating kubeconfig: az %s\n", strings.Join(args, " "))
-	}
+	aksLog.Debugf("updating kubeconfig: az %s", strings.Join(args, " "))
 
 	_, err = p.runAzureCLI(ctx, p.subscriptionID, args...)
 	if err != nil {
@@ -423,6 +523,8 @@ type AKSNodePoolInfo struct {
 	MinCount          int    `json:"minCount,omitempty"`
 	MaxCount          int    `json:"maxCount,omitempty"`
 	Mode              string `json:"mode"`
+	// OrchestratorVersion is the Kubernetes version of the pool's nodes
+	OrchestratorVersion string `json:"orchestratorVersion"`
 }
 
 // ListNodePools returns all node pools for a cluster
@@ -472,9 +574,7 @@ func (p *AKSProvider) CreateNodePool(ctx context.Context, clusterName string, op
 		args = append(args, "--labels", strings.Join(labels, " "))
 	}
 
-	if p.debug {
-		fmt.Printf("[aks] creating node pool: az %s\n", strings.Join(args, " "))
-	}
+	aksLog.Debugf("creating node pool: az %s", strings.Join(args, " "))
 
 	_, err := p.runAzureCLI(ctx, p.subscriptionID, args...)
 	if err != nil {
@@ -507,9 +607,7 @@ func (p *AKSProvider) DeleteNodePool(ctx context.Context, clusterName, nodePoolN
 		"--yes",
 	}
 
-	if p.debug {
-		fmt.Printf("[aks] deleting node pool: az %s\n", strings.Join(args, " "))
-	}
+	aksLog.Debugf("deleting node pool: az %s", strings.Join(args, " "))
 
 	_, err := p.runAzureCLI(ctx, p.subscriptionID, args...)
 	return err
@@ -616,10 +714,7 @@ func (p *AKSProvider) waitForClusterRunning(ctx context.Context, clusterName, re
 			continue
 		}
 
-		if p.debug {
-			fmt.Printf("[aks] cluster %s provisioning state: %s, power state: %s\n",
-				clusterName, cluster.ProvisioningState, cluster.PowerState.Code)
-		}
+		aksLog.Debugf("cluster %s provisioning state: %s, power state: %s", clusterName, cluster.ProvisioningState, cluster.PowerState.Code)
 
 		if cluster.ProvisioningState == "Succeeded" && cluster.PowerState.Code == "Running" {
 			return nil
@@ -647,9 +742,7 @@ func (p *AKSProvider) waitForNodePoolRunning(ctx context.Context, clusterName, n
 
 		for _, np := range nodePools {
 			if np.Name == nodePoolName {
-				if p.debug {
-					fmt.Printf("[aks] node pool %s provisioning state: %s\n", nodePoolName, np.ProvisioningState)
-				}
+				aksLog.Debugf("node pool %s provisioning state: %s", nodePoolName, np.ProvisioningState)
 
 				if np.ProvisioningState == "Succeeded" {
 					return nil
@@ -747,6 +840,9 @@ func (p *AKSProvider) runAzureCLI(ctx context.Context, subscription string, args
 	if subscription != "" {
 		args = append(args, "--subscription", subscription)
 	}
+	if dryrun.Skip(os.Stdout, "az", args) {
+		return "", dryrun.ErrStopped
+	}
 
 	backoffs := []time.Duration{200 * time.Millisecond, 500 * time.Millisecond, 1200 * time.Millisecond}
 	var lastErr error
@@ -761,6 +857,7 @@ func (p *AKSProvider) runAzureCLI(ctx context.Context, subscription string, args
 		cmd.Stderr = &stderr
 
 		err := cmd.Run()
+		audit.RecordStep("az", args, err)
 		if err == nil {
 			return stdout.String(), nil
 		}
@@ -10,6 +10,9 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/dryrun"
 )
 
 // EKSProvider manages AWS EKS clusters
@@ -122,18 +125,14 @@ func (p *EKSProvider) createWithEksctl(ctx context.Context, opts CreateOptions,
 		args = append(args, "--tags", strings.Join(tags, ","))
 	}
 
-	if p.debug {
-		fmt.Printf("[eksctl] creating cluster: eksctl %s\n", strings.Join(args, " "))
-	}
+	eksLog.Debugf("creating cluster: eksctl %s", strings.Join(args, " "))
 
 	output, err := p.runEksctl(ctx, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create EKS cluster: %w", err)
 	}
 
-	if p.debug {
-		fmt.Printf("[eksctl] output: %s\n", output)
-	}
+	eksLog.Debugf("output: %s", output)
 
 	// Retrieve cluster info after creation
 	return p.GetCluster(ctx, opts.Name)
@@ -170,9 +169,7 @@ func (p *EKSProvider) createWithAWSCLI(ctx context.Context, opts CreateOptions,
 		return nil, &ErrInvalidConfiguration{Message: "subnet IDs are required"}
 	}
 
-	if p.debug {
-		fmt.Printf("[aws] creating cluster: aws %s\n", strings.Join(args, " "))
-	}
+	eksLog.Debugf("creating cluster: aws %s", strings.Join(args, " "))
 
 	_, err := p.runAWS(ctx, args...)
 	if err != nil {
@@ -214,9 +211,7 @@ func (p *EKSProvider) deleteWithEksctl(ctx context.Context, clusterName string)
 
 	args = append(args, "--wait")
 
-	if p.debug {
-		fmt.Printf("[eksctl] deleting cluster: eksctl %s\n", strings.Join(args, " "))
-	}
+	eksLog.Debugf("deleting cluster: eksctl %s", strings.Join(args, " "))
 
 	_, err := p.runEksctl(ctx, args...)
 	return err
@@ -244,9 +239,7 @@ func (p *EKSProvider) deleteWithAWSCLI(ctx context.Context, clusterName string)
 		args = append(args, "--profile", p.awsProfile)
 	}
 
-	if p.debug {
-		fmt.Printf("[aws] deleting cluster: aws %s\n", strings.Join(args, " "))
-	}
+	eksLog.Debugf("deleting cluster: aws %s", strings.Join(args, " "))
 
 	_, err = p.runAWS(ctx, args...)
 	return err
@@ -302,9 +295,7 @@ func (p *EKSProvider) scaleWithEksctl(ctx context.Context, clusterName, nodeGrou
 		args = append(args, "--nodes-max", fmt.Sprintf("%d", opts.MaxCount))
 	}
 
-	if p.debug {
-		fmt.Printf("[eksctl] scaling node group: eksctl %s\n", strings.Join(args, " "))
-	}
+	eksLog.Debugf("scaling node group: eksctl %s", strings.Join(args, " "))
 
 	_, err := p.runEksctl(ctx, args...)
 	return err
@@ -334,14 +325,203 @@ func (p *EKSProvider) scaleWithAWSCLI(ctx context.Context, clusterName, nodeGrou
 		args = append(args, "--profile", p.awsProfile)
 	}
 
-	if p.debug {
-		fmt.Printf("[aws] scaling node group: aws %s\n", strings.Join(args, " "))
-	}
+	eksLog.Debugf("scaling node group: aws %s", strings.Join(args, " "))
 
 	_, err := p.runAWS(ctx, args...)
 	return err
 }
 
+// Upgrade moves the control plane to targetVersion, then rolls each managed
+// node group onto the latest EKS optimized AMI for that version. Node groups
+// already on targetVersion are skipped, so a failed upgrade can be rerun.
+func (p *EKSProvider) Upgrade(ctx context.Context, clusterName, targetVersion string) error {
+	if clusterName == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+	target, err := KubernetesMinor(targetVersion)
+	if err != nil {
+		return &ErrInvalidConfiguration{Message: err.Error()}
+	}
+
+	cluster, err := p.describeCluster(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if err := CheckUpgradePath(cluster.Version, target); err != nil {
+		return err
+	}
+
+	if !sameMinor(cluster.Version, target) {
+		eksLog.Debugf("upgrading control plane of %s from %s to %s", clusterName, cluster.Version, target)
+		updateID, err := p.startUpdate(ctx, "eks", "update-cluster-version",
+			"--name", clusterName,
+			"--kubernetes-version", target)
+		if err != nil {
+			return fmt.Errorf("failed to start control plane upgrade: %w", err)
+		}
+		if err := p.waitForUpdate(ctx, clusterName, updateID); err != nil {
+			return fmt.Errorf("control plane upgrade failed: %w", err)
+		}
+	}
+
+	nodeGroups, err := p.ListNodeGroups(ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to list node groups: %w", err)
+	}
+
+	for _, ng := range nodeGroups {
+		if sameMinor(ng.Version, target) {
+			continue
+		}
+		eksLog.Debugf("upgrading node group %s from %s to %s", ng.NodegroupName, ng.Version, target)
+		// Without --release-version EKS picks the latest AMI for the version
+		// and replaces nodes respecting pod disruption budgets
+		updateID, err := p.startUpdate(ctx, "eks", "update-nodegroup-version",
+			"--cluster-name", clusterName,
+			"--nodegroup-name", ng.NodegroupName,
+			"--kubernetes-version", target)
+		if err != nil {
+			return fmt.Errorf("failed to start upgrade of node group %s: %w", ng.NodegroupName, err)
+		}
+		if err := p.waitForUpdate(ctx, clusterName, updateID, "--nodegroup-name", ng.NodegroupName); err != nil {
+			return fmt.Errorf("upgrade of node group %s failed: %w", ng.NodegroupName, err)
+		}
+	}
+
+	return nil
+}
+
+// PatchNodes rolls each managed node group onto the latest EKS optimized AMI
+// for its current Kubernetes version, one group at a time. EKS drains and
+// replaces the nodes of a group respecting pod disruption budgets.
+func (p *EKSProvider) PatchNodes(ctx context.Context, clusterName string, opts PatchOptions) error {
+	if clusterName == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+
+	nodeGroups, err := p.ListNodeGroups(ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to list node groups: %w", err)
+	}
+	if len(nodeGroups) == 0 {
+		return fmt.Errorf("cluster %s has no managed node groups; self-managed nodes are patched by updating their launch template AMI", clusterName)
+	}
+
+	for i, ng := range nodeGroups {
+		opts.progress("eks", "rolling node group %s onto the latest %s AMI (%d/%d)...", ng.NodegroupName, ng.Version, i+1, len(nodeGroups))
+		// Without --kubernetes-version or --release-version EKS picks the
+		// latest AMI release for the group's current version
+		updateID, err := p.startUpdate(ctx, "eks", "update-nodegroup-version",
+			"--cluster-name", clusterName,
+			"--nodegroup-name", ng.NodegroupName)
+		if err != nil {
+			return fmt.Errorf("failed to start AMI update of node group %s: %w", ng.NodegroupName, err)
+		}
+		if err := p.waitForUpdate(ctx, clusterName, updateID, "--nodegroup-name", ng.NodegroupName); err != nil {
+			return fmt.Errorf("AMI update of node group %s failed: %w", ng.NodegroupName, err)
+		}
+		opts.progress("eks", "node group %s is on the latest AMI", ng.NodegroupName)
+	}
+
+	return nil
+}
+
+// startUpdate runs an EKS update call and returns the update ID
+func (p *EKSProvider) startUpdate(ctx context.Context, args ...string) (string, error) {
+	args = append(args, "--output", "json")
+	if p.region != "" {
+		args = append(args, "--region", p.region)
+	}
+	if p.awsProfile != "" {
+		args = append(args, "--profile", p.awsProfile)
+	}
+
+	output, err := p.runAWS(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Update struct {
+			ID string `json:"id"`
+		} `json:"update"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return "", fmt.Errorf("failed to parse update: %w", err)
+	}
+	if result.Update.ID == "" {
+		return "", fmt.Errorf("no update ID returned")
+	}
+	return result.Update.ID, nil
+}
+
+// waitForUpdate polls an EKS update until it succeeds or fails. Node group
+// and add-on updates are looked up with scopeArgs ("--nodegroup-name", ng).
+func (p *EKSProvider) waitForUpdate(ctx context.Context, clusterName, updateID string, scopeArgs ...string) error {
+	args := []string{
+		"eks", "describe-update",
+		"--name", clusterName,
+		"--update-id", updateID,
+		"--output", "json",
+	}
+	args = append(args, scopeArgs...)
+	if p.region != "" {
+		args = append(args, "--region", p.region)
+	}
+	if p.awsProfile != "" {
+		args = append(args, "--profile", p.awsProfile)
+	}
+
+	deadline := time.Now().Add(DefaultUpgradeTimeout)
+	for time.Now().Before(deadline) {
+		output, err := p.runAWS(ctx, args...)
+		if err == nil {
+			status, reason, parseErr := parseEKSUpdate(output)
+			if parseErr != nil {
+				return parseErr
+			}
+			eksLog.Debugf("update %s status: %s", updateID, status)
+			switch status {
+			case "Successful":
+				return nil
+			case "Failed", "Cancelled":
+				return fmt.Errorf("update %s %s: %s", updateID, strings.ToLower(status), reason)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(DefaultPollInterval):
+		}
+	}
+
+	return fmt.Errorf("timeout waiting for update %s", updateID)
+}
+
+// parseEKSUpdate returns the status of `aws eks describe-update` output and
+// the joined error messages of a failed update
+func parseEKSUpdate(output string) (string, string, error) {
+	var result struct {
+		Update struct {
+			Status string `json:"status"`
+			Errors []struct {
+				ErrorCode    string `json:"errorCode"`
+				ErrorMessage string `json:"errorMessage"`
+			} `json:"errors"`
+		} `json:"update"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return "", "", fmt.Errorf("failed to parse update status: %w", err)
+	}
+
+	var reasons []string
+	for _, e := range result.Update.Errors {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", e.ErrorCode, e.ErrorMessage))
+	}
+	return result.Update.Status, strings.Join(reasons, "; "), nil
+}
+
 // GetKubeconfig retrieves and updates kubeconfig for the cluster
 func (p *EKSProvider) GetKubeconfig(ctx context.Context, clusterName string) (string, error) {
 	if clusterName == "" {
@@ -368,9 +548,7 @@ func (p *EKSProvider) GetKubeconfig(ctx context.Context, clusterName string) (st
 		args = append(args, "--profile", p.awsProfile)
 	}
 
-	if p.debug {
-		fmt.Printf("[aws] updating kubeconfig: aws %s\n", strings.Join(args, " "))
-	}
+	eksLog.Debugf("updating kubeconfig: aws %s", strings.Join(args, " "))
 
 	_, err = p.runAWS(ctx, args...)
 	if err != nil {
@@ -615,9 +793,7 @@ func (p *EKSProvider) createNodeGroupWithEksctl(ctx context.Context, clusterName
 		args = append(args, "--node-labels", strings.Join(labels, ","))
 	}
 
-	if p.debug {
-		fmt.Printf("[eksctl] creating node group: eksctl %s\n", strings.Join(args, " "))
-	}
+	eksLog.Debugf("creating node group: eksctl %s", strings.Join(args, " "))
 
 	_, err := p.runEksctl(ctx, args...)
 	return err
@@ -679,9 +855,7 @@ func (p *EKSProvider) createNodeGroupWithAWSCLI(ctx context.Context, clusterName
 	// Node role is required for AWS CLI method
 	// This would typically come from configuration or be created beforehand
 
-	if p.debug {
-		fmt.Printf("[aws] creating node group: aws %s\n", strings.Join(args, " "))
-	}
+	eksLog.Debugf("creating node group: aws %s", strings.Join(args, " "))
 
 	_, err := p.runAWS(ctx, args...)
 	if err != nil {
@@ -702,9 +876,7 @@ func (p *EKSProvider) waitForNodeGroupActive(ctx context.Context, clusterName, n
 			continue
 		}
 
-		if p.debug {
-			fmt.Printf("[aws] node group %s status: %s\n", nodeGroupName, ng.Status)
-		}
+		eksLog.Debugf("node group %s status: %s", nodeGroupName, ng.Status)
 
 		if ng.Status == "ACTIVE" {
 			return nil
@@ -757,6 +929,7 @@ type eksClusterInfo struct {
 type eksNodeGroupInfo struct {
 	NodegroupName string `json:"nodegroupName"`
 	Status        string `json:"status"`
+	Version       string `json:"version"`
 	DesiredSize   int    `json:"desiredSize"`
 	MinSize       int    `json:"minSize"`
 	MaxSize       int    `json:"maxSize"`
@@ -860,6 +1033,7 @@ func (p *EKSProvider) describeNodeGroup(ctx context.Context, clusterName, nodeGr
 		Nodegroup struct {
 			NodegroupName string `json:"nodegroupName"`
 			Status        string `json:"status"`
+			Version       string `json:"version"`
 			ScalingConfig struct {
 				DesiredSize int `json:"desiredSize"`
 				MinSize     int `json:"minSize"`
@@ -875,6 +1049,7 @@ func (p *EKSProvider) describeNodeGroup(ctx context.Context, clusterName, nodeGr
 	return &eksNodeGroupInfo{
 		NodegroupName: result.Nodegroup.NodegroupName,
 		Status:        result.Nodegroup.Status,
+		Version:       result.Nodegroup.Version,
 		DesiredSize:   result.Nodegroup.ScalingConfig.DesiredSize,
 		MinSize:       result.Nodegroup.ScalingConfig.MinSize,
 		MaxSize:       result.Nodegroup.ScalingConfig.MaxSize,
@@ -895,9 +1070,7 @@ func (p *EKSProvider) deleteNodeGroup(ctx context.Context, clusterName, nodeGrou
 		args = append(args, "--profile", p.awsProfile)
 	}
 
-	if p.debug {
-		fmt.Printf("[aws] deleting node group: aws %s\n", strings.Join(args, " "))
-	}
+	eksLog.Debugf("deleting node group: aws %s", strings.Join(args, " "))
 
 	_, err := p.runAWS(ctx, args...)
 	if err != nil {
@@ -921,9 +1094,7 @@ func (p *EKSProvider) waitForClusterActive(ctx context.Context, clusterName, pro
 			continue
 		}
 
-		if p.debug {
-			fmt.Printf("[aws] cluster %s status: %s\n", clusterName, cluster.Status)
-		}
+		eksLog.Debugf("cluster %s status: %s", clusterName, cluster.Status)
 
 		if cluster.Status == "ACTIVE" {
 			return nil
@@ -948,9 +1119,7 @@ func (p *EKSProvider) waitForNodeGroupDeleted(ctx context.Context, clusterName,
 			return nil
 		}
 
-		if p.debug {
-			fmt.Printf("[aws] waiting for node group %s deletion\n", nodeGroupName)
-		}
+		eksLog.Debugf("waiting for node group %s deletion", nodeGroupName)
 
 		time.Sleep(DefaultPollInterval)
 	}
@@ -968,6 +1137,9 @@ func (p *EKSProvider) hasEksctl() bool {
 }
 
 func (p *EKSProvider) runEksctl(ctx context.Context, args ...string) (string, error) {
+	if dryrun.Skip(os.Stdout, "eksctl", args) {
+		return "", dryrun.ErrStopped
+	}
 	backoffs := []time.Duration{200 * time.Millisecond, 500 * time.Millisecond, 1200 * time.Millisecond}
 	var lastErr error
 	var lastStderr string
@@ -981,6 +1153,7 @@ func (p *EKSProvider) runEksctl(ctx context.Context, args ...string) (string, er
 		cmd.Stderr = &stderr
 
 		err := cmd.Run()
+		audit.RecordStep("eksctl", args, err)
 		if err == nil {
 			return stdout.String(), nil
 		}
@@ -1015,6 +1188,9 @@ func (p *EKSProvider) runAWS(ctx context.Context, args ...string) (string, error
 
 	// Add no-cli-pager to prevent interactive output
 	args = append(args, "--no-cli-pager")
+	if dryrun.Skip(os.Stdout, "aws", args) {
+		return "", dryrun.ErrStopped
+	}
 
 	backoffs := []time.Duration{200 * time.Millisecond, 500 * time.Millisecond, 1200 * time.Millisecond}
 	var lastErr error
@@ -1029,6 +1205,7 @@ func (p *EKSProvider) runAWS(ctx context.Context, args ...string) (string, error
 		cmd.Stderr = &stderr
 
 		err := cmd.Run()
+		audit.RecordStep("aws", args, err)
 		if err == nil {
 			return stdout.String(), nil
 		}
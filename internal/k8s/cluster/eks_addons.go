@@ -0,0 +1,422 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultAddonTimeout is how long to wait for an EKS add-on to become active
+const DefaultAddonTimeout = 20 * time.Minute
+
+// EKSAddonSpec describes a well-known EKS add-on and the IAM permissions its
+// service account needs through IRSA
+type EKSAddonSpec struct {
+	Name           string   // EKS add-on name
+	Aliases        []string // names users call it by
+	Description    string
+	Namespace      string // namespace of the service account
+	ServiceAccount string // service account that assumes the IRSA role
+	PolicyARN      string // AWS managed policy for the role, empty when none is needed
+}
+
+// NeedsIRSA reports whether the add-on needs an IAM role for its service account
+func (s EKSAddonSpec) NeedsIRSA() bool {
+	return s.PolicyARN != ""
+}
+
+// eksAddonCatalog lists the add-ons clanker knows how to set up
+var eksAddonCatalog = []EKSAddonSpec{
+	{
+		Name:           "vpc-cni",
+		Aliases:        []string{"aws-node", "cni", "vpc cni", "amazon vpc cni"},
+		Description:    "Amazon VPC CNI pod networking",
+		Namespace:      "kube-system",
+		ServiceAccount: "aws-node",
+		PolicyARN:      "arn:aws:iam::aws:policy/AmazonEKS_CNI_Policy",
+	},
+	{
+		Name:        "coredns",
+		Aliases:     []string{"dns", "core dns"},
+		Description: "CoreDNS cluster DNS",
+	},
+	{
+		Name:        "kube-proxy",
+		Aliases:     []string{"kubeproxy"},
+		Description: "kube-proxy service networking",
+	},
+	{
+		Name:           "aws-ebs-csi-driver",
+		Aliases:        []string{"ebs-csi", "ebs csi", "ebs-csi-driver", "ebs csi driver", "ebs"},
+		Description:    "Amazon EBS CSI driver for persistent volumes",
+		Namespace:      "kube-system",
+		ServiceAccount: "ebs-csi-controller-sa",
+		PolicyARN:      "arn:aws:iam::aws:policy/service-role/AmazonEBSCSIDriverPolicy",
+	},
+	{
+		Name:        "metrics-server",
+		Aliases:     []string{"metrics server"},
+		Description: "Kubernetes Metrics Server for kubectl top and HPA",
+	},
+}
+
+// EKSAddonCatalog returns the well-known add-ons
+func EKSAddonCatalog() []EKSAddonSpec {
+	return append([]EKSAddonSpec(nil), eksAddonCatalog...)
+}
+
+// LookupEKSAddon finds a well-known add-on by name or alias
+func LookupEKSAddon(name string) (EKSAddonSpec, bool) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	for _, spec := range eksAddonCatalog {
+		if spec.Name == n {
+			return spec, true
+		}
+		for _, alias := range spec.Aliases {
+			if alias == n {
+				return spec, true
+			}
+		}
+	}
+	return EKSAddonSpec{}, false
+}
+
+// EKSAddon is an add-on installed on a cluster
+type EKSAddon struct {
+	Name                  string   `json:"name"`
+	Version               string   `json:"version"`
+	Status                string   `json:"status"`
+	ServiceAccountRoleARN string   `json:"serviceAccountRoleArn,omitempty"`
+	Issues                []string `json:"issues,omitempty"`
+}
+
+// EKSAddonVersion is an add-on version available for a Kubernetes version
+type EKSAddonVersion struct {
+	Version string `json:"version"`
+	Default bool   `json:"default"`
+}
+
+// AddonOptions configures an add-on install or upgrade
+type AddonOptions struct {
+	Name                  string
+	Version               string // the default version for the cluster when empty
+	ServiceAccountRoleARN string // IRSA role for the add-on's service account
+}
+
+// ListAddons returns the add-ons installed on a cluster
+func (p *EKSProvider) ListAddons(ctx context.Context, clusterName string) ([]EKSAddon, error) {
+	if clusterName == "" {
+		return nil, &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+
+	output, err := p.runAWS(ctx, p.withAWSScope("eks", "list-addons", "--cluster-name", clusterName, "--output", "json")...)
+	if err != nil {
+		if strings.Contains(err.Error(), "ResourceNotFoundException") {
+			return nil, &ErrClusterNotFound{ClusterName: clusterName}
+		}
+		return nil, err
+	}
+
+	var result struct {
+		Addons []string `json:"addons"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse add-ons: %w", err)
+	}
+
+	addons := make([]EKSAddon, 0, len(result.Addons))
+	for _, name := range result.Addons {
+		addon, err := p.describeAddon(ctx, clusterName, name)
+		if err != nil {
+			return nil, err
+		}
+		addons = append(addons, *addon)
+	}
+	return addons, nil
+}
+
+// AddonVersions returns the versions of an add-on available for a
+// Kubernetes version, newest first
+func (p *EKSProvider) AddonVersions(ctx context.Context, addonName, kubernetesVersion string) ([]EKSAddonVersion, error) {
+	output, err := p.runAWS(ctx, p.withAWSScope(
+		"eks", "describe-addon-versions",
+		"--addon-name", addonName,
+		"--kubernetes-version", kubernetesVersion,
+		"--output", "json",
+	)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseEKSAddonVersions(output, kubernetesVersion)
+}
+
+// InstallAddon installs an add-on and waits for it to become active
+func (p *EKSProvider) InstallAddon(ctx context.Context, clusterName string, opts AddonOptions) error {
+	if clusterName == "" || opts.Name == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name and add-on name are required"}
+	}
+
+	version, err := p.resolveAddonVersion(ctx, clusterName, opts, false)
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		"eks", "create-addon",
+		"--cluster-name", clusterName,
+		"--addon-name", opts.Name,
+		"--addon-version", version,
+		// Adopt resources EKS created outside the add-on (vpc-cni, coredns
+		// and kube-proxy exist on every cluster)
+		"--resolve-conflicts", "OVERWRITE",
+	}
+	if opts.ServiceAccountRoleARN != "" {
+		args = append(args, "--service-account-role-arn", opts.ServiceAccountRoleARN)
+	}
+
+	eksLog.Debugf("installing add-on %s %s on %s", opts.Name, version, clusterName)
+	if _, err := p.runAWS(ctx, p.withAWSScope(args...)...); err != nil {
+		return fmt.Errorf("failed to create add-on %s: %w", opts.Name, err)
+	}
+
+	return p.waitForAddonActive(ctx, clusterName, opts.Name)
+}
+
+// UpgradeAddon moves an installed add-on to opts.Version, or the newest
+// version for the cluster, keeping configuration changed on the cluster
+func (p *EKSProvider) UpgradeAddon(ctx context.Context, clusterName string, opts AddonOptions) error {
+	if clusterName == "" || opts.Name == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name and add-on name are required"}
+	}
+
+	current, err := p.describeAddon(ctx, clusterName, opts.Name)
+	if err != nil {
+		return err
+	}
+	version, err := p.resolveAddonVersion(ctx, clusterName, opts, true)
+	if err != nil {
+		return err
+	}
+	if opts.Version == "" && compareAddonVersions(current.Version, version) >= 0 && opts.ServiceAccountRoleARN == "" {
+		eksLog.Debugf("add-on %s already at %s", opts.Name, current.Version)
+		return nil
+	}
+
+	args := []string{
+		"eks", "update-addon",
+		"--cluster-name", clusterName,
+		"--addon-name", opts.Name,
+		"--addon-version", version,
+		"--resolve-conflicts", "PRESERVE",
+	}
+	if opts.ServiceAccountRoleARN != "" {
+		args = append(args, "--service-account-role-arn", opts.ServiceAccountRoleARN)
+	}
+
+	eksLog.Debugf("upgrading add-on %s from %s to %s on %s", opts.Name, current.Version, version, clusterName)
+	updateID, err := p.startUpdate(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update add-on %s: %w", opts.Name, err)
+	}
+	return p.waitForUpdate(ctx, clusterName, updateID, "--addon-name", opts.Name)
+}
+
+// resolveAddonVersion returns the requested version, or else the default
+// (or with latest set, the newest) version for the cluster's Kubernetes
+// version
+func (p *EKSProvider) resolveAddonVersion(ctx context.Context, clusterName string, opts AddonOptions, latest bool) (string, error) {
+	if opts.Version != "" {
+		return opts.Version, nil
+	}
+
+	info, err := p.describeCluster(ctx, clusterName)
+	if err != nil {
+		return "", err
+	}
+	versions, err := p.AddonVersions(ctx, opts.Name, info.Version)
+	if err != nil {
+		return "", fmt.Errorf("failed to list versions of %s: %w", opts.Name, err)
+	}
+	if len(versions) == 0 {
+		return "", fmt.Errorf("add-on %s has no version for Kubernetes %s", opts.Name, info.Version)
+	}
+	if latest {
+		return versions[0].Version, nil
+	}
+	for _, v := range versions {
+		if v.Default {
+			return v.Version, nil
+		}
+	}
+	return versions[0].Version, nil
+}
+
+func (p *EKSProvider) describeAddon(ctx context.Context, clusterName, addonName string) (*EKSAddon, error) {
+	output, err := p.runAWS(ctx, p.withAWSScope(
+		"eks", "describe-addon",
+		"--cluster-name", clusterName,
+		"--addon-name", addonName,
+		"--output", "json",
+	)...)
+	if err != nil {
+		if strings.Contains(err.Error(), "ResourceNotFoundException") {
+			return nil, fmt.Errorf("add-on %s is not installed on %s", addonName, clusterName)
+		}
+		return nil, err
+	}
+	return parseEKSAddon(output)
+}
+
+// waitForAddonActive polls a new add-on until it is active or has failed
+func (p *EKSProvider) waitForAddonActive(ctx context.Context, clusterName, addonName string) error {
+	deadline := time.Now().Add(DefaultAddonTimeout)
+	var last *EKSAddon
+	for time.Now().Before(deadline) {
+		addon, err := p.describeAddon(ctx, clusterName, addonName)
+		if err == nil {
+			last = addon
+			eksLog.Debugf("add-on %s status: %s", addonName, addon.Status)
+			switch addon.Status {
+			case "ACTIVE":
+				return nil
+			case "CREATE_FAILED":
+				return fmt.Errorf("add-on %s failed to install: %s", addonName, strings.Join(addon.Issues, "; "))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(DefaultPollInterval):
+		}
+	}
+
+	if last != nil && len(last.Issues) > 0 {
+		return fmt.Errorf("timeout waiting for add-on %s (%s): %s", addonName, last.Status, strings.Join(last.Issues, "; "))
+	}
+	return fmt.Errorf("timeout waiting for add-on %s to become active", addonName)
+}
+
+// withAWSScope appends the provider's region and profile to aws CLI args
+func (p *EKSProvider) withAWSScope(args ...string) []string {
+	if p.region != "" {
+		args = append(args, "--region", p.region)
+	}
+	if p.awsProfile != "" {
+		args = append(args, "--profile", p.awsProfile)
+	}
+	return args
+}
+
+// parseEKSAddon parses `aws eks describe-addon` output
+func parseEKSAddon(output string) (*EKSAddon, error) {
+	var result struct {
+		Addon struct {
+			AddonName             string `json:"addonName"`
+			AddonVersion          string `json:"addonVersion"`
+			Status                string `json:"status"`
+			ServiceAccountRoleArn string `json:"serviceAccountRoleArn"`
+			Health                struct {
+				Issues []struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"issues"`
+			} `json:"health"`
+		} `json:"addon"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse add-on: %w", err)
+	}
+
+	addon := &EKSAddon{
+		Name:                  result.Addon.AddonName,
+		Version:               result.Addon.AddonVersion,
+		Status:                result.Addon.Status,
+		ServiceAccountRoleARN: result.Addon.ServiceAccountRoleArn,
+	}
+	for _, issue := range result.Addon.Health.Issues {
+		addon.Issues = append(addon.Issues, fmt.Sprintf("%s: %s", issue.Code, issue.Message))
+	}
+	return addon, nil
+}
+
+// parseEKSAddonVersions parses `aws eks describe-addon-versions` output into
+// the versions compatible with kubernetesVersion, newest first
+func parseEKSAddonVersions(output, kubernetesVersion string) ([]EKSAddonVersion, error) {
+	var result struct {
+		Addons []struct {
+			AddonVersions []struct {
+				AddonVersion    string `json:"addonVersion"`
+				Compatibilities []struct {
+					ClusterVersion string `json:"clusterVersion"`
+					DefaultVersion bool   `json:"defaultVersion"`
+				} `json:"compatibilities"`
+			} `json:"addonVersions"`
+		} `json:"addons"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse add-on versions: %w", err)
+	}
+
+	var versions []EKSAddonVersion
+	for _, addon := range result.Addons {
+		for _, v := range addon.AddonVersions {
+			for _, c := range v.Compatibilities {
+				if c.ClusterVersion == kubernetesVersion {
+					versions = append(versions, EKSAddonVersion{Version: v.AddonVersion, Default: c.DefaultVersion})
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareAddonVersions(versions[i].Version, versions[j].Version) > 0
+	})
+	return versions, nil
+}
+
+// compareAddonVersions orders versions such as "v1.18.1-eksbuild.3" by their
+// numeric parts
+func compareAddonVersions(a, b string) int {
+	an, bn := addonVersionNumbers(a), addonVersionNumbers(b)
+	for i := 0; i < len(an) || i < len(bn); i++ {
+		var x, y int
+		if i < len(an) {
+			x = an[i]
+		}
+		if i < len(bn) {
+			y = bn[i]
+		}
+		if x != y {
+			if x > y {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+func addonVersionNumbers(version string) []int {
+	var nums []int
+	n, inNumber := 0, false
+	for _, r := range version {
+		if r >= '0' && r <= '9' {
+			n = n*10 + int(r-'0')
+			inNumber = true
+			continue
+		}
+		if inNumber {
+			nums = append(nums, n)
+			n, inNumber = 0, false
+		}
+	}
+	if inNumber {
+		nums = append(nums, n)
+	}
+	return nums
+}
@@ -0,0 +1,87 @@
+package cluster
+
+import "testing"
+
+func TestLookupEKSAddon(t *testing.T) {
+	tests := map[string]string{
+		"ebs-csi":            "aws-ebs-csi-driver",
+		"EBS CSI Driver":     "aws-ebs-csi-driver",
+		"aws-ebs-csi-driver": "aws-ebs-csi-driver",
+		"aws-node":           "vpc-cni",
+		"metrics-server":     "metrics-server",
+		"coredns":            "coredns",
+	}
+	for name, want := range tests {
+		spec, ok := LookupEKSAddon(name)
+		if !ok || spec.Name != want {
+			t.Errorf("LookupEKSAddon(%q) = %q, %v; want %q", name, spec.Name, ok, want)
+		}
+	}
+
+	if _, ok := LookupEKSAddon("istio"); ok {
+		t.Error("expected istio to be unknown")
+	}
+
+	if spec, _ := LookupEKSAddon("ebs-csi"); !spec.NeedsIRSA() || spec.ServiceAccount != "ebs-csi-controller-sa" {
+		t.Errorf("ebs-csi IRSA spec = %+v", spec)
+	}
+	if spec, _ := LookupEKSAddon("coredns"); spec.NeedsIRSA() {
+		t.Error("coredns should not need IRSA")
+	}
+}
+
+func TestParseEKSAddon(t *testing.T) {
+	addon, err := parseEKSAddon(`{"addon":{"addonName":"aws-ebs-csi-driver","clusterName":"prod","status":"DEGRADED","addonVersion":"v1.30.0-eksbuild.1","serviceAccountRoleArn":"arn:aws:iam::123456789012:role/ebs","health":{"issues":[{"code":"InsufficientNumberOfReplicas","message":"The add-on is unhealthy because it doesn't have the desired number of replicas."}]}}}`)
+	if err != nil {
+		t.Fatalf("parseEKSAddon: %v", err)
+	}
+	if addon.Name != "aws-ebs-csi-driver" || addon.Version != "v1.30.0-eksbuild.1" || addon.Status != "DEGRADED" {
+		t.Errorf("addon = %+v", addon)
+	}
+	if addon.ServiceAccountRoleARN != "arn:aws:iam::123456789012:role/ebs" {
+		t.Errorf("role = %q", addon.ServiceAccountRoleARN)
+	}
+	if len(addon.Issues) != 1 {
+		t.Errorf("issues = %v", addon.Issues)
+	}
+}
+
+func TestParseEKSAddonVersions(t *testing.T) {
+	output := `{"addons":[{"addonName":"vpc-cni","addonVersions":[
+		{"addonVersion":"v1.18.1-eksbuild.3","compatibilities":[{"clusterVersion":"1.30","defaultVersion":true},{"clusterVersion":"1.29","defaultVersion":false}]},
+		{"addonVersion":"v1.18.10-eksbuild.1","compatibilities":[{"clusterVersion":"1.30","defaultVersion":false}]},
+		{"addonVersion":"v1.16.0-eksbuild.1","compatibilities":[{"clusterVersion":"1.29","defaultVersion":true}]}
+	]}]}`
+
+	versions, err := parseEKSAddonVersions(output, "1.30")
+	if err != nil {
+		t.Fatalf("parseEKSAddonVersions: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("versions = %+v, want 2", versions)
+	}
+	// v1.18.10 sorts above v1.18.1
+	if versions[0].Version != "v1.18.10-eksbuild.1" || versions[0].Default {
+		t.Errorf("versions[0] = %+v", versions[0])
+	}
+	if versions[1].Version != "v1.18.1-eksbuild.3" || !versions[1].Default {
+		t.Errorf("versions[1] = %+v", versions[1])
+	}
+}
+
+func TestCompareAddonVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.11.1-eksbuild.9", "v1.11.1-eksbuild.9", 0},
+		{"v1.11.1-eksbuild.10", "v1.11.1-eksbuild.9", 1},
+		{"v1.10.1-eksbuild.1", "v1.11.1-eksbuild.1", -1},
+		{"v1.29.0-eksbuild.2", "v1.29.0", 1},
+	}
+	for _, tt := range tests {
+		if got := compareAddonVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareAddonVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PreviewDelete lists what Delete would remove: node groups, add-ons, node
+// instances, in-use network interfaces and the security groups EKS or eksctl
+// manage. Load balancers, volumes and security groups that Services and
+// PersistentVolumes created are tagged to the cluster but outlive it; they
+// are listed as retained.
+func (p *EKSProvider) PreviewDelete(ctx context.Context, clusterName string) (*TeardownReport, error) {
+	if clusterName == "" {
+		return nil, &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+	if _, err := p.describeCluster(ctx, clusterName); err != nil {
+		return nil, err
+	}
+
+	report := &TeardownReport{ClusterName: clusterName, ClusterType: ClusterTypeEKS}
+
+	nodeGroups, err := p.listNodeGroups(ctx, clusterName)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("could not list node groups: %v", err))
+	}
+	for _, ng := range nodeGroups {
+		res := TeardownResource{Type: "node-group", ID: ng, Name: ng}
+		if info, err := p.describeNodeGroup(ctx, clusterName, ng); err == nil {
+			res.Detail = fmt.Sprintf("%d nodes", info.DesiredSize)
+		}
+		report.Resources = append(report.Resources, res)
+	}
+
+	addons, err := p.ListAddons(ctx, clusterName)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("could not list add-ons: %v", err))
+	}
+	for _, addon := range addons {
+		report.Resources = append(report.Resources, TeardownResource{Type: "addon", ID: addon.Name, Name: addon.Name, Detail: addon.Version})
+	}
+
+	tagged, err := p.clusterTaggedResources(ctx, clusterName)
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("could not list tagged resources: %v", err))
+	}
+	for _, r := range tagged {
+		if res, ok := classifyEKSResource(r); ok {
+			report.Resources = append(report.Resources, res)
+		}
+	}
+
+	output, err := p.runAWS(ctx, p.withAWSScope("ec2", "describe-network-interfaces",
+		"--filters", fmt.Sprintf("Name=tag:cluster.k8s.amazonaws.com/name,Values=%s", clusterName),
+		"--output", "json")...)
+	if err == nil {
+		var enis []networkInterface
+		if enis, err = parseNetworkInterfaces(output); err == nil {
+			for _, eni := range enis {
+				res := TeardownResource{Type: "network-interface", ID: eni.ID, Detail: eni.Description}
+				if eni.Status == "available" {
+					// Detached CNI interfaces are not cleaned up by EKS
+					res.Retained = true
+					res.Detail = "detached VPC CNI interface"
+				}
+				report.Resources = append(report.Resources, res)
+			}
+		}
+	}
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("could not list network interfaces: %v", err))
+	}
+
+	if n := len(report.Retained()); n > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"%d resources outlive the cluster; delete LoadBalancer Services and PersistentVolumeClaims first, or run `clanker k8s sweep eks` afterwards", n))
+	}
+
+	return report, nil
+}
+
+// FindOrphans finds load balancers, target groups, volumes, security groups
+// and detached VPC CNI network interfaces tagged to EKS clusters that no
+// longer exist in the region. Clusters with a kubeadm security group
+// (<name>-k8s-sg) are skipped; the kubeadm sweeper handles those.
+func (p *EKSProvider) FindOrphans(ctx context.Context) ([]OrphanResource, error) {
+	clusters, err := p.ListClusters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+	live := make(map[string]bool)
+	for _, c := range clusters {
+		live[c.Name] = true
+	}
+
+	output, err := p.runAWS(ctx, p.withAWSScope(kubeadmSecurityGroupArgs()...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kubeadm security groups: %w", err)
+	}
+	kubeadmClusters, err := parseEC2ClusterNames(output)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range kubeadmClusters {
+		live[name] = true
+	}
+
+	output, err = p.runAWS(ctx, p.withAWSScope("resourcegroupstaggingapi", "get-resources",
+		"--resource-type-filters",
+		"elasticloadbalancing:loadbalancer", "elasticloadbalancing:targetgroup", "ec2:volume", "ec2:security-group",
+		"--output", "json")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tagged resources: %w", err)
+	}
+	tagged, err := parseTaggedResources(output)
+	if err != nil {
+		return nil, err
+	}
+	orphans := findEKSOrphans(tagged, live)
+
+	output, err = p.runAWS(ctx, p.withAWSScope("ec2", "describe-network-interfaces",
+		"--filters", "Name=tag-key,Values=cluster.k8s.amazonaws.com/name", "Name=status,Values=available",
+		"--output", "json")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+	enis, err := parseNetworkInterfaces(output)
+	if err != nil {
+		return nil, err
+	}
+	for _, eni := range enis {
+		name := taggedClusterName(eni.Tags)
+		if name == "" || live[name] {
+			continue
+		}
+		orphans = append(orphans, OrphanResource{
+			Type:    "network-interface",
+			ID:      eni.ID,
+			Cluster: name,
+			Reason:  "detached VPC CNI interface; cluster no longer exists",
+		})
+	}
+
+	return orphans, nil
+}
+
+// DeleteOrphans deletes orphans found by FindOrphans. Load balancers go
+// first so that the security groups they use can be deleted after them.
+func (p *EKSProvider) DeleteOrphans(ctx context.Context, orphans []OrphanResource) error {
+	var errs []error
+	for _, orphan := range sortOrphansForDeletion(orphans) {
+		args, err := eksOrphanDeleteArgs(orphan)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		eksLog.Debugf("deleting %s %s", orphan.Type, orphan.ID)
+		if err := p.deleteOrphan(ctx, orphan, args); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", orphan.Type, orphan.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deleteOrphan runs a delete command. Security groups stay in use for a
+// few minutes after the load balancer that referenced them is deleted, so
+// DependencyViolation is retried.
+func (p *EKSProvider) deleteOrphan(ctx context.Context, orphan OrphanResource, args []string) error {
+	const attempts = 6
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		_, err = p.runAWS(ctx, p.withAWSScope(args...)...)
+		if err == nil || orphan.Type != "security-group" || !strings.Contains(err.Error(), "DependencyViolation") {
+			return err
+		}
+		if attempt == attempts-1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(DefaultPollInterval):
+		}
+	}
+	return err
+}
+
+// eksOrphanDeleteArgs returns the aws CLI arguments that delete an orphan
+func eksOrphanDeleteArgs(orphan OrphanResource) ([]string, error) {
+	switch orphan.Type {
+	case "load-balancer":
+		if isELBv2ARN(orphan.ID) {
+			return []string{"elbv2", "delete-load-balancer", "--load-balancer-arn", orphan.ID}, nil
+		}
+		return []string{"elb", "delete-load-balancer", "--load-balancer-name", orphan.Name}, nil
+	case "target-group":
+		return []string{"elbv2", "delete-target-group", "--target-group-arn", orphan.ID}, nil
+	case "network-interface":
+		return []string{"ec2", "delete-network-interface", "--network-interface-id", orphan.ID}, nil
+	case "volume":
+		return []string{"ec2", "delete-volume", "--volume-id", orphan.ID}, nil
+	case "security-group":
+		return []string{"ec2", "delete-security-group", "--group-id", orphan.ID}, nil
+	default:
+		return nil, fmt.Errorf("cannot delete %s %s: unsupported resource type", orphan.Type, orphan.ID)
+	}
+}
+
+// isELBv2ARN reports whether a load balancer ARN is an application, network
+// or gateway load balancer rather than a classic one
+func isELBv2ARN(arn string) bool {
+	for _, kind := range []string{":loadbalancer/app/", ":loadbalancer/net/", ":loadbalancer/gwy/"} {
+		if strings.Contains(arn, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterTaggedResources returns the resources tagged to a cluster by
+// Kubernetes, EKS or the AWS Load Balancer Controller. Tag filters in one
+// call are ANDed, so each tag is a separate call.
+func (p *EKSProvider) clusterTaggedResources(ctx context.Context, clusterName string) ([]taggedResource, error) {
+	filters := []string{
+		"Key=" + clusterTagPrefix + clusterName,
+		"Key=aws:eks:cluster-name,Values=" + clusterName,
+		"Key=elbv2.k8s.aws/cluster,Values=" + clusterName,
+	}
+
+	seen := make(map[string]bool)
+	var resources []taggedResource
+	for _, filter := range filters {
+		output, err := p.runAWS(ctx, p.withAWSScope("resourcegroupstaggingapi", "get-resources",
+			"--tag-filters", filter, "--output", "json")...)
+		if err != nil {
+			return resources, err
+		}
+		tagged, err := parseTaggedResources(output)
+		if err != nil {
+			return resources, err
+		}
+		for _, r := range tagged {
+			if !seen[r.ARN] {
+				seen[r.ARN] = true
+				resources = append(resources, r)
+			}
+		}
+	}
+	return resources, nil
+}
+
+// findEKSOrphans picks the tagged resources of clusters that are not live.
+// Resources shared with other clusters (kubernetes.io/cluster/<name>=shared)
+// are never orphans.
+func findEKSOrphans(tagged []taggedResource, live map[string]bool) []OrphanResource {
+	var orphans []OrphanResource
+	for _, r := range tagged {
+		name := taggedClusterName(r.Tags)
+		if name == "" || live[name] || r.Tags[clusterTagPrefix+name] == "shared" {
+			continue
+		}
+		res, ok := classifyEKSResource(r)
+		if !ok {
+			continue
+		}
+		reason := "cluster no longer exists"
+		if res.Detail != "" {
+			reason += "; " + res.Detail
+		}
+		orphans = append(orphans, OrphanResource{
+			Type:    res.Type,
+			ID:      res.ID,
+			Name:    res.Name,
+			Cluster: name,
+			Reason:  reason,
+		})
+	}
+	return orphans
+}
+
+// classifyEKSResource describes a tagged resource and whether it survives
+// cluster deletion. EKS resources (the cluster, node groups, add-ons) and
+// network interfaces are listed separately and are skipped.
+func classifyEKSResource(r taggedResource) (TeardownResource, bool) {
+	parts := strings.SplitN(r.ARN, ":", 6)
+	if len(parts) < 6 {
+		return TeardownResource{}, false
+	}
+	service, resource := parts[2], parts[5]
+	resourceType, id, _ := strings.Cut(resource, "/")
+
+	switch service {
+	case "eks":
+		return TeardownResource{}, false
+	case "ec2":
+		switch resourceType {
+		case "instance":
+			res := TeardownResource{Type: "instance", ID: id, Name: r.Tags["Name"]}
+			if ng := r.Tags["eks:nodegroup-name"]; ng != "" {
+				res.Detail = "node group " + ng
+			}
+			return res, true
+		case "security-group":
+			res := TeardownResource{Type: "security-group", ID: id, Name: r.Tags["Name"]}
+			switch {
+			case r.Tags["aws:eks:cluster-name"] != "":
+				res.Detail = "EKS cluster security group"
+			case r.Tags["alpha.eksctl.io/cluster-name"] != "":
+				res.Detail = "eksctl security group"
+			default:
+				res.Retained = true
+				res.Detail = "created for a LoadBalancer Service"
+			}
+			return res, true
+		case "volume":
+			res := TeardownResource{Type: "volume", ID: id, Name: r.Tags["Name"], Retained: true}
+			if pvc := r.Tags["kubernetes.io/created-for/pvc/name"]; pvc != "" {
+				res.Detail = fmt.Sprintf("PVC %s/%s", r.Tags["kubernetes.io/created-for/pvc/namespace"], pvc)
+			}
+			return res, true
+		case "network-interface":
+			return TeardownResource{}, false
+		default:
+			return TeardownResource{Type: resourceType, ID: id, Retained: true, Detail: "tagged to the cluster"}, true
+		}
+	case "elasticloadbalancing":
+		segments := strings.Split(resource, "/")
+		switch resourceType {
+		case "loadbalancer":
+			// loadbalancer/<name> (classic) or loadbalancer/app/<name>/<id>
+			name := segments[len(segments)-1]
+			if len(segments) >= 4 {
+				name = segments[2]
+			}
+			res := TeardownResource{Type: "load-balancer", ID: r.ARN, Name: name, Retained: true}
+			if svc := r.Tags["kubernetes.io/service-name"]; svc != "" {
+				res.Detail = "Service " + svc
+			}
+			return res, true
+		case "targetgroup":
+			return TeardownResource{Type: "target-group", ID: r.ARN, Name: segments[len(segments)-1], Retained: true}, true
+		}
+	}
+	return TeardownResource{Type: service + ":" + resourceType, ID: r.ARN, Retained: true, Detail: "tagged to the cluster"}, true
+}
+
+// taggedResource is one entry of `aws resourcegroupstaggingapi get-resources`
+type taggedResource struct {
+	ARN  string
+	Tags map[string]string
+}
+
+func parseTaggedResources(output string) ([]taggedResource, error) {
+	var result struct {
+		ResourceTagMappingList []struct {
+			ResourceARN string `json:"ResourceARN"`
+			Tags        []struct {
+				Key   string `json:"Key"`
+				Value string `json:"Value"`
+			} `json:"Tags"`
+		} `json:"ResourceTagMappingList"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse tagged resources: %w", err)
+	}
+
+	resources := make([]taggedResource, 0, len(result.ResourceTagMappingList))
+	for _, m := range result.ResourceTagMappingList {
+		tags := make(map[string]string, len(m.Tags))
+		for _, tag := range m.Tags {
+			tags[tag.Key] = tag.Value
+		}
+		resources = append(resources, taggedResource{ARN: m.ResourceARN, Tags: tags})
+	}
+	return resources, nil
+}
+
+// networkInterface is the subset of `aws ec2 describe-network-interfaces`
+// used here
+type networkInterface struct {
+	ID          string
+	Status      string
+	Description string
+	Tags        map[string]string
+}
+
+func parseNetworkInterfaces(output string) ([]networkInterface, error) {
+	var result struct {
+		NetworkInterfaces []struct {
+			NetworkInterfaceID string `json:"NetworkInterfaceId"`
+			Status             string `json:"Status"`
+			Description        string `json:"Description"`
+			TagSet             []struct {
+				Key   string `json:"Key"`
+				Value string `json:"Value"`
+			} `json:"TagSet"`
+		} `json:"NetworkInterfaces"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse network interfaces: %w", err)
+	}
+
+	enis := make([]networkInterface, 0, len(result.NetworkInterfaces))
+	for _, n := range result.NetworkInterfaces {
+		tags := make(map[string]string, len(n.TagSet))
+		for _, tag := range n.TagSet {
+			tags[tag.Key] = tag.Value
+		}
+		enis = append(enis, networkInterface{
+			ID:          n.NetworkInterfaceID,
+			Status:      n.Status,
+			Description: n.Description,
+			Tags:        tags,
+		})
+	}
+	return enis, nil
+}
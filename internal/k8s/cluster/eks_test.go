@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 )
@@ -504,3 +505,21 @@ func findInString(s, substr string) bool {
 	}
 	return false
 }
+
+func TestParseEKSUpdate(t *testing.T) {
+	status, reasons, err := parseEKSUpdate(`{"update":{"id":"abc","status":"InProgress","errors":[]}}`)
+	if err != nil {
+		t.Fatalf("parseEKSUpdate: %v", err)
+	}
+	if status != "InProgress" || reasons != "" {
+		t.Errorf("got status=%q reasons=%q", status, reasons)
+	}
+
+	status, reasons, err = parseEKSUpdate(`{"update":{"id":"abc","status":"Failed","errors":[{"errorCode":"PodEvictionFailure","errorMessage":"Reached max retries while trying to evict pods"}]}}`)
+	if err != nil {
+		t.Fatalf("parseEKSUpdate: %v", err)
+	}
+	if status != "Failed" || !strings.Contains(reasons, "PodEvictionFailure") {
+		t.Errorf("got status=%q reasons=%q", status, reasons)
+	}
+}
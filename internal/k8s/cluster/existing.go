@@ -52,6 +52,11 @@ func (p *ExistingProvider) Scale(ctx context.Context, clusterName string, opts S
 	return fmt.Errorf("scale not directly supported for existing clusters; use kubectl or the original provisioning tool")
 }
 
+// Upgrade is not supported; the cluster's own tooling owns its version
+func (p *ExistingProvider) Upgrade(ctx context.Context, clusterName, targetVersion string) error {
+	return fmt.Errorf("upgrade not supported for existing clusters; use the original provisioning tool")
+}
+
 // GetKubeconfig returns the kubeconfig path
 func (p *ExistingProvider) GetKubeconfig(ctx context.Context, clusterName string) (string, error) {
 	if _, err := os.Stat(p.kubeconfig); err != nil {
@@ -191,9 +196,7 @@ func (p *ExistingProvider) runKubectl(ctx context.Context, contextName string, a
 	}
 	cmdArgs = append(cmdArgs, args...)
 
-	if p.debug {
-		fmt.Printf("[kubectl] %s\n", strings.Join(cmdArgs, " "))
-	}
+	logger.Debugf("%s", strings.Join(cmdArgs, " "))
 
 	cmd := exec.CommandContext(ctx, "kubectl", cmdArgs...)
 	cmd.Env = os.Environ()
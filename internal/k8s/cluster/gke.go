@@ -11,6 +11,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/dryrun"
 	gcpinfra "github.com/bgdnvk/clanker/internal/gcp"
 )
 
@@ -64,59 +66,20 @@ func (p *GKEProvider) Create(ctx context.Context, opts CreateOptions) (*ClusterI
 		return nil, &ErrInvalidConfiguration{Message: "GCP project is required"}
 	}
 
+	args, err := gkeCreateArgs(opts, region)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if cluster already exists
 	existing, _ := p.GetCluster(ctx, opts.Name)
 	if existing != nil {
 		return nil, &ErrClusterExists{ClusterName: opts.Name}
 	}
 
-	args := []string{
-		"container", "clusters", "create", opts.Name,
-		"--region", region,
-	}
-
-	// Node configuration
-	nodeCount := opts.WorkerCount
-	if nodeCount <= 0 {
-		nodeCount = 1
-	}
-	args = append(args, "--num-nodes", fmt.Sprintf("%d", nodeCount))
-
-	if opts.WorkerType != "" {
-		args = append(args, "--machine-type", opts.WorkerType)
-	}
-
-	if opts.KubernetesVersion != "" {
-		args = append(args, "--cluster-version", opts.KubernetesVersion)
-	}
-
-	// Network configuration
-	if opts.GCPNetwork != "" {
-		args = append(args, "--network", opts.GCPNetwork)
-	}
-	if opts.GCPSubnetwork != "" {
-		args = append(args, "--subnetwork", opts.GCPSubnetwork)
-	}
-
-	// Preemptible nodes for cost savings
-	if opts.Preemptible {
-		args = append(args, "--preemptible")
-	}
-
-	// Tags/Labels
-	if len(opts.Tags) > 0 {
-		var labels []string
-		for k, v := range opts.Tags {
-			labels = append(labels, fmt.Sprintf("%s=%s", k, v))
-		}
-		args = append(args, "--labels", strings.Join(labels, ","))
-	}
-
-	if p.debug {
-		fmt.Printf("[gke] creating cluster: gcloud %s --project %s\n", strings.Join(args, " "), project)
-	}
+	gkeLog.Debugf("creating cluster: gcloud %s --project %s", strings.Join(args, " "), project)
 
-	_, err := p.runGcloud(ctx, project, args...)
+	_, err = p.runGcloud(ctx, project, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GKE cluster: %w", err)
 	}
@@ -146,9 +109,7 @@ func (p *GKEProvider) Delete(ctx context.Context, clusterName string) error {
 		"--quiet",
 	}
 
-	if p.debug {
-		fmt.Printf("[gke] deleting cluster: gcloud %s --project %s\n", strings.Join(args, " "), p.projectID)
-	}
+	gkeLog.Debugf("deleting cluster: gcloud %s --project %s", strings.Join(args, " "), p.projectID)
 
 	_, err := p.runGcloud(ctx, p.projectID, args...)
 	if err != nil {
@@ -193,14 +154,115 @@ func (p *GKEProvider) Scale(ctx context.Context, clusterName string, opts ScaleO
 		}
 	}
 
-	if p.debug {
-		fmt.Printf("[gke] scaling cluster: gcloud %s --project %s\n", strings.Join(args, " "), p.projectID)
-	}
+	gkeLog.Debugf("scaling cluster: gcloud %s --project %s", strings.Join(args, " "), p.projectID)
 
 	_, err := p.runGcloud(ctx, p.projectID, args...)
 	return err
 }
 
+// Upgrade moves the control plane to targetVersion, then upgrades each node
+// pool to the control plane version. GKE surge-upgrades the nodes of a pool
+// and waits for each operation to finish.
+func (p *GKEProvider) Upgrade(ctx context.Context, clusterName, targetVersion string) error {
+	if clusterName == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+
+	region := p.region
+	if region == "" {
+		return &ErrInvalidConfiguration{Message: "region is required for upgrade"}
+	}
+
+	target, err := KubernetesMinor(targetVersion)
+	if err != nil {
+		return &ErrInvalidConfiguration{Message: err.Error()}
+	}
+
+	cluster, err := p.describeCluster(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if err := CheckUpgradePath(cluster.CurrentMasterVersion, target); err != nil {
+		return err
+	}
+
+	if !sameMinor(cluster.CurrentMasterVersion, target) {
+		// A minor version makes GKE pick the latest patch available in it
+		args := []string{
+			"container", "clusters", "upgrade", clusterName,
+			"--master",
+			"--cluster-version", target,
+			"--region", region,
+			"--quiet",
+		}
+		gkeLog.Debugf("upgrading control plane: gcloud %s --project %s", strings.Join(args, " "), p.projectID)
+		if _, err := p.runGcloud(ctx, p.projectID, args...); err != nil {
+			return fmt.Errorf("control plane upgrade failed: %w", err)
+		}
+	}
+
+	nodePools, err := p.listNodePools(ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to list node pools: %w", err)
+	}
+
+	for _, np := range nodePools {
+		if sameMinor(np.Version, target) {
+			continue
+		}
+		// Without --cluster-version node pools move to the control plane version
+		args := []string{
+			"container", "clusters", "upgrade", clusterName,
+			"--node-pool", np.Name,
+			"--region", region,
+			"--quiet",
+		}
+		gkeLog.Debugf("upgrading node pool: gcloud %s --project %s", strings.Join(args, " "), p.projectID)
+		if _, err := p.runGcloud(ctx, p.projectID, args...); err != nil {
+			return fmt.Errorf("upgrade of node pool %s failed: %w", np.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// PatchNodes recreates the nodes of each node pool on the latest node image
+// for the pool's current version, one pool at a time. Upgrading a pool to
+// the version it already runs makes GKE surge-replace its nodes.
+func (p *GKEProvider) PatchNodes(ctx context.Context, clusterName string, opts PatchOptions) error {
+	if clusterName == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+
+	region := p.region
+	if region == "" {
+		return &ErrInvalidConfiguration{Message: "region is required for node patching"}
+	}
+
+	nodePools, err := p.listNodePools(ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to list node pools: %w", err)
+	}
+
+	for i, np := range nodePools {
+		opts.progress("gke", "recreating node pool %s on the latest %s image (%d/%d)...", np.Name, np.Version, i+1, len(nodePools))
+		args := []string{
+			"container", "clusters", "upgrade", clusterName,
+			"--node-pool", np.Name,
+			"--cluster-version", np.Version,
+			"--region", region,
+			"--quiet",
+		}
+		gkeLog.Debugf("patching node pool: gcloud %s --project %s", strings.Join(args, " "), p.projectID)
+		if _, err := p.runGcloud(ctx, p.projectID, args...); err != nil {
+			return fmt.Errorf("image update of node pool %s failed: %w", np.Name, err)
+		}
+		opts.progress("gke", "node pool %s is on the latest image", np.Name)
+	}
+
+	return nil
+}
+
 // GetKubeconfig retrieves and updates kubeconfig for the cluster
 func (p *GKEProvider) GetKubeconfig(ctx context.Context, clusterName string) (string, error) {
 	if clusterName == "" {
@@ -232,9 +294,7 @@ func (p *GKEProvider) GetKubeconfig(ctx context.Context, clusterName string) (st
 		"--region", region,
 	}
 
-	if p.debug {
-		fmt.Printf("[gke] updating kubeconfig: gcloud %s --project %s\n", strings.Join(args, " "), p.projectID)
-	}
+	gkeLog.Debugf("updating kubeconfig: gcloud %s --project %s", strings.Join(args, " "), p.projectID)
 
 	_, err = p.runGcloud(ctx, p.projectID, args...)
 	if err != nil {
@@ -263,6 +323,16 @@ func (p *GKEProvider) Health(ctx context.Context, clusterName string) (*HealthSt
 	clusterStatus := cluster.Status
 	status.Components["cluster"] = clusterStatus
 
+	// Release channel, maintenance window and any queued auto-upgrade
+	config, err := p.serverConfig(ctx)
+	if err != nil {
+		DebugLog(p.debug, "gke", "reading server config: %v", err)
+		config = nil
+	}
+	for name, value := range gkeUpgradeComponents(cluster, config, status.LastChecked) {
+		status.Components[name] = value
+	}
+
 	// Check if cluster is running
 	if clusterStatus != "RUNNING" {
 		status.Healthy = false
@@ -394,6 +464,7 @@ func (p *GKEProvider) GetCluster(ctx context.Context, clusterName string) (*Clus
 type GKENodePoolInfo struct {
 	Name             string `json:"name"`
 	Status           string `json:"status"`
+	Version          string `json:"version"`
 	InitialNodeCount int    `json:"initialNodeCount"`
 	Config           struct {
 		MachineType string `json:"machineType"`
@@ -452,9 +523,7 @@ func (p *GKEProvider) CreateNodePool(ctx context.Context, clusterName string, op
 		args = append(args, "--node-labels", strings.Join(labels, ","))
 	}
 
-	if p.debug {
-		fmt.Printf("[gke] creating node pool: gcloud %s --project %s\n", strings.Join(args, " "), p.projectID)
-	}
+	gkeLog.Debugf("creating node pool: gcloud %s --project %s", strings.Join(args, " "), p.projectID)
 
 	_, err := p.runGcloud(ctx, p.projectID, args...)
 	if err != nil {
@@ -486,9 +555,7 @@ func (p *GKEProvider) DeleteNodePool(ctx context.Context, clusterName, nodePoolN
 		"--quiet",
 	}
 
-	if p.debug {
-		fmt.Printf("[gke] deleting node pool: gcloud %s --project %s\n", strings.Join(args, " "), p.projectID)
-	}
+	gkeLog.Debugf("deleting node pool: gcloud %s --project %s", strings.Join(args, " "), p.projectID)
 
 	_, err := p.runGcloud(ctx, p.projectID, args...)
 	return err
@@ -506,6 +573,18 @@ type gkeClusterInfo struct {
 	Subnetwork           string            `json:"subnetwork"`
 	CreateTime           string            `json:"createTime"`
 	NodePools            []gkeNodePoolInfo `json:"nodePools"`
+	Autopilot            struct {
+		Enabled bool `json:"enabled"`
+	} `json:"autopilot"`
+	ReleaseChannel struct {
+		Channel string `json:"channel"`
+	} `json:"releaseChannel"`
+	Autoscaling struct {
+		EnableNodeAutoprovisioning bool `json:"enableNodeAutoprovisioning"`
+	} `json:"autoscaling"`
+	MaintenancePolicy struct {
+		Window gkeMaintenanceWindow `json:"window"`
+	} `json:"maintenancePolicy"`
 }
 
 type gkeNodePoolInfo struct {
@@ -592,9 +671,7 @@ func (p *GKEProvider) waitForClusterRunning(ctx context.Context, clusterName, pr
 			continue
 		}
 
-		if p.debug {
-			fmt.Printf("[gke] cluster %s status: %s\n", clusterName, cluster.Status)
-		}
+		gkeLog.Debugf("cluster %s status: %s", clusterName, cluster.Status)
 
 		if cluster.Status == "RUNNING" {
 			return nil
@@ -621,9 +698,7 @@ func (p *GKEProvider) waitForNodePoolRunning(ctx context.Context, clusterName, n
 
 		for _, np := range nodePools {
 			if np.Name == nodePoolName {
-				if p.debug {
-					fmt.Printf("[gke] node pool %s status: %s\n", nodePoolName, np.Status)
-				}
+				gkeLog.Debugf("node pool %s status: %s", nodePoolName, np.Status)
 
 				if np.Status == "RUNNING" {
 					return nil
@@ -653,6 +728,9 @@ func (p *GKEProvider) runGcloud(ctx context.Context, project string, args ...str
 
 	// Add project flag
 	args = append(args, "--project", project)
+	if dryrun.Skip(os.Stdout, "gcloud", args) {
+		return "", dryrun.ErrStopped
+	}
 
 	backoffs := []time.Duration{200 * time.Millisecond, 500 * time.Millisecond, 1200 * time.Millisecond}
 	var lastErr error
@@ -667,6 +745,7 @@ func (p *GKEProvider) runGcloud(ctx context.Context, project string, args ...str
 		cmd.Stderr = &stderr
 
 		err := cmd.Run()
+		audit.RecordStep(bin, args, err)
 		if err == nil {
 			return stdout.String(), nil
 		}
@@ -0,0 +1,237 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// GKEReleaseChannels are the channels a GKE cluster can be enrolled in,
+// fastest first. "none" leaves the cluster on a static version.
+var GKEReleaseChannels = []string{"rapid", "regular", "stable", "extended", "none"}
+
+// NormalizeGKEReleaseChannel lowercases channel and checks it names a GKE
+// release channel
+func NormalizeGKEReleaseChannel(channel string) (string, error) {
+	c := strings.ToLower(strings.TrimSpace(channel))
+	if c == "unspecified" {
+		c = "none"
+	}
+	if !slices.Contains(GKEReleaseChannels, c) {
+		return "", &ErrInvalidConfiguration{Message: fmt.Sprintf("unknown release channel %q (use %s)", channel, strings.Join(GKEReleaseChannels, ", "))}
+	}
+	return c, nil
+}
+
+// GKEAutoProvisioning holds the cluster-wide resource limits node
+// auto-provisioning creates node pools within
+type GKEAutoProvisioning struct {
+	MinCPU      int
+	MaxCPU      int
+	MinMemoryGB int
+	MaxMemoryGB int
+}
+
+// validate checks the limits GKE requires to turn auto-provisioning on
+func (a GKEAutoProvisioning) validate() error {
+	if a.MaxCPU <= 0 || a.MaxMemoryGB <= 0 {
+		return &ErrInvalidConfiguration{Message: "node auto-provisioning needs a maximum CPU and memory for the cluster"}
+	}
+	if a.MinCPU < 0 || a.MinMemoryGB < 0 || a.MinCPU > a.MaxCPU || a.MinMemoryGB > a.MaxMemoryGB {
+		return &ErrInvalidConfiguration{Message: "node auto-provisioning minimums must be between 0 and the maximums"}
+	}
+	return nil
+}
+
+func (a GKEAutoProvisioning) args() []string {
+	args := []string{
+		"--enable-autoprovisioning",
+		"--max-cpu", fmt.Sprintf("%d", a.MaxCPU),
+		"--max-memory", fmt.Sprintf("%d", a.MaxMemoryGB),
+	}
+	if a.MinCPU > 0 {
+		args = append(args, "--min-cpu", fmt.Sprintf("%d", a.MinCPU))
+	}
+	if a.MinMemoryGB > 0 {
+		args = append(args, "--min-memory", fmt.Sprintf("%d", a.MinMemoryGB))
+	}
+	return args
+}
+
+// ValidateGKECreateOptions checks the Autopilot, release channel and node
+// auto-provisioning settings in opts, so a bad combination is caught before
+// a plan is shown
+func ValidateGKECreateOptions(opts CreateOptions) error {
+	_, err := gkeCreateArgs(opts, "")
+	return err
+}
+
+// gkeCreateArgs returns the gcloud arguments that create the cluster opts
+// describes in region. Autopilot clusters use create-auto, which takes no
+// node settings.
+func gkeCreateArgs(opts CreateOptions, region string) ([]string, error) {
+	channel := ""
+	if opts.GKEReleaseChannel != "" {
+		c, err := NormalizeGKEReleaseChannel(opts.GKEReleaseChannel)
+		if err != nil {
+			return nil, err
+		}
+		channel = c
+	}
+
+	var args []string
+	if opts.GKEAutopilot {
+		switch {
+		case opts.Preemptible:
+			return nil, &ErrInvalidConfiguration{Message: "Autopilot clusters do not take preemptible nodes; request Spot Pods with a cloud.google.com/gke-spot node selector instead"}
+		case opts.GKEAutoProvisioning != nil:
+			return nil, &ErrInvalidConfiguration{Message: "Autopilot clusters always auto-provision nodes"}
+		case channel == "none":
+			return nil, &ErrInvalidConfiguration{Message: "Autopilot clusters must be enrolled in a release channel"}
+		}
+		args = []string{
+			"container", "clusters", "create-auto", opts.Name,
+			"--region", region,
+		}
+	} else {
+		args = []string{
+			"container", "clusters", "create", opts.Name,
+			"--region", region,
+		}
+
+		// Node configuration
+		nodeCount := opts.WorkerCount
+		if nodeCount <= 0 {
+			nodeCount = 1
+		}
+		args = append(args, "--num-nodes", fmt.Sprintf("%d", nodeCount))
+
+		if opts.WorkerType != "" {
+			args = append(args, "--machine-type", opts.WorkerType)
+		}
+
+		// Preemptible nodes for cost savings
+		if opts.Preemptible {
+			args = append(args, "--preemptible")
+		}
+
+		if opts.GKEAutoProvisioning != nil {
+			if err := opts.GKEAutoProvisioning.validate(); err != nil {
+				return nil, err
+			}
+			args = append(args, opts.GKEAutoProvisioning.args()...)
+		}
+	}
+
+	if opts.KubernetesVersion != "" {
+		args = append(args, "--cluster-version", opts.KubernetesVersion)
+	}
+	if channel != "" {
+		args = append(args, "--release-channel", channel)
+	}
+
+	// Network configuration
+	if opts.GCPNetwork != "" {
+		args = append(args, "--network", opts.GCPNetwork)
+	}
+	if opts.GCPSubnetwork != "" {
+		args = append(args, "--subnetwork", opts.GCPSubnetwork)
+	}
+
+	// Tags/Labels
+	if len(opts.Tags) > 0 {
+		var labels []string
+		for k, v := range opts.Tags {
+			labels = append(labels, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Strings(labels)
+		args = append(args, "--labels", strings.Join(labels, ","))
+	}
+
+	return args, nil
+}
+
+// SetReleaseChannel enrolls a cluster in channel, or takes it off release
+// channels with "none". GKE then upgrades the cluster to the channel's
+// versions in its maintenance windows.
+func (p *GKEProvider) SetReleaseChannel(ctx context.Context, clusterName, channel string) error {
+	if clusterName == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+	c, err := NormalizeGKEReleaseChannel(channel)
+	if err != nil {
+		return err
+	}
+
+	region := p.region
+	if region == "" {
+		return &ErrInvalidConfiguration{Message: "region is required"}
+	}
+
+	if c == "none" {
+		cluster, err := p.describeCluster(ctx, clusterName)
+		if err != nil {
+			return err
+		}
+		if cluster.Autopilot.Enabled {
+			return &ErrInvalidConfiguration{Message: "Autopilot clusters must stay enrolled in a release channel"}
+		}
+	}
+
+	args := []string{
+		"container", "clusters", "update", clusterName,
+		"--release-channel", c,
+		"--region", region,
+		"--quiet",
+	}
+
+	gkeLog.Debugf("setting release channel: gcloud %s --project %s", strings.Join(args, " "), p.projectID)
+
+	if _, err := p.runGcloud(ctx, p.projectID, args...); err != nil {
+		return fmt.Errorf("failed to set release channel: %w", err)
+	}
+	return nil
+}
+
+// SetNodeAutoProvisioning turns node auto-provisioning on within limits, or
+// off when limits is nil. Turning it off keeps the node pools it created.
+func (p *GKEProvider) SetNodeAutoProvisioning(ctx context.Context, clusterName string, limits *GKEAutoProvisioning) error {
+	if clusterName == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+	if limits != nil {
+		if err := limits.validate(); err != nil {
+			return err
+		}
+	}
+
+	region := p.region
+	if region == "" {
+		return &ErrInvalidConfiguration{Message: "region is required"}
+	}
+
+	cluster, err := p.describeCluster(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if cluster.Autopilot.Enabled {
+		return &ErrInvalidConfiguration{Message: "Autopilot clusters always auto-provision nodes"}
+	}
+
+	args := []string{"container", "clusters", "update", clusterName}
+	if limits != nil {
+		args = append(args, limits.args()...)
+	} else {
+		args = append(args, "--no-enable-autoprovisioning")
+	}
+	args = append(args, "--region", region, "--quiet")
+
+	gkeLog.Debugf("updating node auto-provisioning: gcloud %s --project %s", strings.Join(args, " "), p.projectID)
+
+	if _, err := p.runGcloud(ctx, p.projectID, args...); err != nil {
+		return fmt.Errorf("failed to update node auto-provisioning: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gkeTimeWindow is a start and end time in RFC 3339
+type gkeTimeWindow struct {
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+}
+
+// gkeMaintenanceWindow is a cluster's maintenancePolicy.window. A cluster
+// has a daily window, a recurring window, or neither, plus any exclusions
+// that hold upgrades back.
+type gkeMaintenanceWindow struct {
+	DailyMaintenanceWindow *struct {
+		StartTime string `json:"startTime"` // "03:00", in UTC
+		Duration  string `json:"duration"`  // ISO 8601, e.g. "PT4H0M0S"
+	} `json:"dailyMaintenanceWindow"`
+	RecurringWindow *struct {
+		Window     gkeTimeWindow `json:"window"`
+		Recurrence string        `json:"recurrence"` // RFC 5545 RRULE
+	} `json:"recurringWindow"`
+	MaintenanceExclusions map[string]gkeTimeWindow `json:"maintenanceExclusions"`
+}
+
+// gkeDailyWindowLength is how long a daily window lasts when GKE does not
+// report its duration
+const gkeDailyWindowLength = 4 * time.Hour
+
+// next returns the start and length of the maintenance window now falls in,
+// or else of the next one. ok is false when the cluster has no window or its
+// recurrence is one clanker does not evaluate.
+func (w gkeMaintenanceWindow) next(now time.Time) (time.Time, time.Duration, bool) {
+	now = now.UTC()
+	switch {
+	case w.DailyMaintenanceWindow != nil:
+		clock, err := time.Parse("15:04", w.DailyMaintenanceWindow.StartTime)
+		if err != nil {
+			return time.Time{}, 0, false
+		}
+		length := parseISODuration(w.DailyMaintenanceWindow.Duration)
+		if length <= 0 {
+			length = gkeDailyWindowLength
+		}
+		// Yesterday's window may still be open past midnight
+		for d := -1; d <= 1; d++ {
+			day := now.AddDate(0, 0, d)
+			start := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+			if start.Add(length).After(now) {
+				return start, length, true
+			}
+		}
+
+	case w.RecurringWindow != nil:
+		first, err := time.Parse(time.RFC3339, w.RecurringWindow.Window.StartTime)
+		if err != nil {
+			return time.Time{}, 0, false
+		}
+		end, err := time.Parse(time.RFC3339, w.RecurringWindow.Window.EndTime)
+		if err != nil || !end.After(first) {
+			return time.Time{}, 0, false
+		}
+		first = first.UTC()
+		length := end.Sub(first)
+		if first.After(now) {
+			return first, length, true
+		}
+		days, ok := recurrenceDays(w.RecurringWindow.Recurrence, first.Weekday())
+		if !ok {
+			return time.Time{}, 0, false
+		}
+		for d := -int(length.Hours()/24) - 1; d <= 7; d++ {
+			day := now.AddDate(0, 0, d)
+			if !days[day.Weekday()] {
+				continue
+			}
+			start := time.Date(day.Year(), day.Month(), day.Day(), first.Hour(), first.Minute(), first.Second(), 0, time.UTC)
+			if start.Add(length).After(now) {
+				return start, length, true
+			}
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+// exclusionAt returns the name and end of the maintenance exclusion t falls
+// in, if any
+func (w gkeMaintenanceWindow) exclusionAt(t time.Time) (string, time.Time, bool) {
+	names := make([]string, 0, len(w.MaintenanceExclusions))
+	for name := range w.MaintenanceExclusions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ex := w.MaintenanceExclusions[name]
+		start, err := time.Parse(time.RFC3339, ex.StartTime)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(time.RFC3339, ex.EndTime)
+		if err != nil {
+			continue
+		}
+		if !t.Before(start) && t.Before(end) {
+			return name, end.UTC(), true
+		}
+	}
+	return "", time.Time{}, false
+}
+
+// recurrenceDays returns the weekdays a daily or weekly RRULE repeats on.
+// Weekly rules without BYDAY repeat on the weekday of the first window.
+func recurrenceDays(rrule string, firstDay time.Weekday) (map[time.Weekday]bool, bool) {
+	parts := map[string]string{}
+	for _, field := range strings.Split(strings.TrimPrefix(strings.ToUpper(rrule), "RRULE:"), ";") {
+		if k, v, ok := strings.Cut(field, "="); ok {
+			parts[k] = v
+		}
+	}
+	days := map[time.Weekday]bool{}
+	switch parts["FREQ"] {
+	case "DAILY":
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			days[d] = true
+		}
+	case "WEEKLY":
+		if parts["BYDAY"] == "" {
+			days[firstDay] = true
+			break
+		}
+		weekdays := map[string]time.Weekday{
+			"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+			"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+		}
+		for _, name := range strings.Split(parts["BYDAY"], ",") {
+			d, ok := weekdays[name]
+			if !ok {
+				return nil, false
+			}
+			days[d] = true
+		}
+	default:
+		return nil, false
+	}
+	return days, true
+}
+
+// parseISODuration parses the hour, minute and second parts of an ISO 8601
+// duration such as "PT4H0M0S"; anything else returns 0
+func parseISODuration(s string) time.Duration {
+	rest, ok := strings.CutPrefix(strings.ToUpper(strings.TrimSpace(s)), "PT")
+	if !ok || rest == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(strings.ToLower(rest))
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// describe summarizes when GKE may next run maintenance on the cluster
+func (w gkeMaintenanceWindow) describe(now time.Time) string {
+	start, length, ok := w.next(now)
+	switch {
+	case ok && !start.After(now):
+		return fmt.Sprintf("open until %s", formatGKETime(start.Add(length)))
+	case ok:
+		return fmt.Sprintf("next %s for %s", formatGKETime(start), formatWindowLength(length))
+	case w.RecurringWindow != nil:
+		return fmt.Sprintf("recurring %s", w.RecurringWindow.Recurrence)
+	}
+	return "none set, GKE may upgrade at any time"
+}
+
+func formatGKETime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+func formatWindowLength(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return strings.TrimSuffix(d.Round(time.Minute).String(), "0s")
+}
+
+// gkeServerConfig is the part of get-server-config listing each release
+// channel's versions
+type gkeServerConfig struct {
+	Channels []struct {
+		Channel              string `json:"channel"`
+		DefaultVersion       string `json:"defaultVersion"`
+		UpgradeTargetVersion string `json:"upgradeTargetVersion"`
+	} `json:"channels"`
+}
+
+// autoUpgradeTarget returns the version GKE auto-upgrades control planes on
+// channel to, or "" when the channel is not listed
+func (c gkeServerConfig) autoUpgradeTarget(channel string) string {
+	for _, ch := range c.Channels {
+		if !strings.EqualFold(ch.Channel, channel) {
+			continue
+		}
+		if ch.UpgradeTargetVersion != "" {
+			return ch.UpgradeTargetVersion
+		}
+		return ch.DefaultVersion
+	}
+	return ""
+}
+
+// compareGKEVersions orders versions such as "1.30.5-gke.1014001" by their
+// numeric parts
+func compareGKEVersions(a, b string) int {
+	split := func(v string) []int {
+		var nums []int
+		for _, f := range strings.FieldsFunc(v, func(r rune) bool { return r < '0' || r > '9' }) {
+			n, _ := strconv.Atoi(f)
+			nums = append(nums, n)
+		}
+		return nums
+	}
+	an, bn := split(a), split(b)
+	for i := 0; i < len(an) && i < len(bn); i++ {
+		if an[i] != bn[i] {
+			if an[i] < bn[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(an) - len(bn)
+}
+
+// gkeUpgradeComponents returns the health components describing the
+// cluster's GKE features, its maintenance window and any control plane
+// auto-upgrade the release channel has queued. config may be nil when the
+// server config could not be read.
+func gkeUpgradeComponents(cluster *gkeClusterInfo, config *gkeServerConfig, now time.Time) map[string]string {
+	components := map[string]string{}
+	if cluster.Autopilot.Enabled {
+		components["autopilot"] = "enabled"
+	} else if cluster.Autoscaling.EnableNodeAutoprovisioning {
+		components["node-auto-provisioning"] = "enabled"
+	} else {
+		components["node-auto-provisioning"] = "disabled"
+	}
+
+	channel := strings.ToLower(cluster.ReleaseChannel.Channel)
+	if channel == "" || channel == "unspecified" {
+		channel = "none"
+	}
+	components["release-channel"] = channel
+
+	window := cluster.MaintenancePolicy.Window
+	components["maintenance-window"] = window.describe(now)
+
+	if channel == "none" || config == nil {
+		return components
+	}
+	target := config.autoUpgradeTarget(channel)
+	if target == "" || compareGKEVersions(cluster.CurrentMasterVersion, target) >= 0 {
+		return components
+	}
+	// An exclusion holds the upgrade back if it covers the next window
+	start, _, hasWindow := window.next(now)
+	at := now
+	if hasWindow && start.After(now) {
+		at = start
+	}
+	upgrade := fmt.Sprintf("control plane to %s", target)
+	switch name, end, excluded := window.exclusionAt(at); {
+	case excluded:
+		upgrade += fmt.Sprintf(", held by exclusion %s until %s", name, formatGKETime(end))
+	case hasWindow && start.After(now):
+		upgrade += fmt.Sprintf(" in the window starting %s", formatGKETime(start))
+	case hasWindow:
+		upgrade += " in the current window"
+	default:
+		upgrade += " pending"
+	}
+	components["auto-upgrade"] = upgrade
+	return components
+}
+
+// serverConfig returns the release channel versions GKE offers in the
+// provider's region
+func (p *GKEProvider) serverConfig(ctx context.Context) (*gkeServerConfig, error) {
+	region := p.region
+	if region == "" {
+		return nil, &ErrInvalidConfiguration{Message: "region is required"}
+	}
+
+	output, err := p.runGcloud(ctx, p.projectID, "container", "get-server-config", "--region", region, "--format=json")
+	if err != nil {
+		return nil, err
+	}
+
+	var config gkeServerConfig
+	if err := json.Unmarshal([]byte(output), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse server config: %w", err)
+	}
+	return &config, nil
+}
@@ -0,0 +1,121 @@
+package cluster
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// gkeTestNow is a Thursday
+var gkeTestNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+func parseGKEWindow(t *testing.T, raw string) gkeMaintenanceWindow {
+	t.Helper()
+	var w gkeMaintenanceWindow
+	if err := json.Unmarshal([]byte(raw), &w); err != nil {
+		t.Fatalf("parse window: %v", err)
+	}
+	return w
+}
+
+func TestGKEMaintenanceWindowDescribe(t *testing.T) {
+	tests := []struct {
+		name   string
+		window string
+		now    time.Time
+		want   string
+	}{
+		{"daily later", `{"dailyMaintenanceWindow": {"startTime": "03:00", "duration": "PT4H0M0S"}}`, gkeTestNow, "next 2026-10-16 03:00 UTC for 4h"},
+		{"daily open", `{"dailyMaintenanceWindow": {"startTime": "10:00"}}`, gkeTestNow, "open until 2026-10-15 14:00 UTC"},
+		{"daily past midnight", `{"dailyMaintenanceWindow": {"startTime": "22:00", "duration": "PT4H"}}`,
+			time.Date(2026, 10, 15, 1, 0, 0, 0, time.UTC), "open until 2026-10-15 02:00 UTC"},
+		{"weekend", `{"recurringWindow": {"window": {"startTime": "2025-01-04T02:00:00Z", "endTime": "2025-01-04T08:00:00Z"},
+			"recurrence": "FREQ=WEEKLY;BYDAY=SA,SU"}}`, gkeTestNow, "next 2026-10-17 02:00 UTC for 6h"},
+		{"weekly on first day", `{"recurringWindow": {"window": {"startTime": "2025-01-07T23:30:00Z", "endTime": "2025-01-08T01:00:00Z"},
+			"recurrence": "FREQ=WEEKLY"}}`, gkeTestNow, "next 2026-10-20 23:30 UTC for 1h30m"},
+		{"not yet started", `{"recurringWindow": {"window": {"startTime": "2026-11-01T00:00:00Z", "endTime": "2026-11-01T04:00:00Z"},
+			"recurrence": "FREQ=DAILY"}}`, gkeTestNow, "next 2026-11-01 00:00 UTC for 4h"},
+		{"monthly", `{"recurringWindow": {"window": {"startTime": "2025-01-01T00:00:00Z", "endTime": "2025-01-01T04:00:00Z"},
+			"recurrence": "FREQ=MONTHLY;BYMONTHDAY=1"}}`, gkeTestNow, "recurring FREQ=MONTHLY;BYMONTHDAY=1"},
+		{"none", `{}`, gkeTestNow, "none set, GKE may upgrade at any time"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseGKEWindow(t, tt.window).describe(tt.now); got != tt.want {
+				t.Errorf("describe = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGKEUpgradeComponents(t *testing.T) {
+	var config gkeServerConfig
+	if err := json.Unmarshal([]byte(`{"channels": [
+		{"channel": "STABLE", "defaultVersion": "1.30.5-gke.100"},
+		{"channel": "REGULAR", "defaultVersion": "1.31.1-gke.100", "upgradeTargetVersion": "1.31.2-gke.300"}
+	]}`), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	var cluster gkeClusterInfo
+	if err := json.Unmarshal([]byte(`{
+		"name": "prod", "currentMasterVersion": "1.30.9-gke.1000",
+		"releaseChannel": {"channel": "REGULAR"},
+		"autoscaling": {"enableNodeAutoprovisioning": true},
+		"maintenancePolicy": {"window": {"dailyMaintenanceWindow": {"startTime": "03:00", "duration": "PT4H0M0S"}}}
+	}`), &cluster); err != nil {
+		t.Fatal(err)
+	}
+
+	got := gkeUpgradeComponents(&cluster, &config, gkeTestNow)
+	want := map[string]string{
+		"node-auto-provisioning": "enabled",
+		"release-channel":        "regular",
+		"maintenance-window":     "next 2026-10-16 03:00 UTC for 4h",
+		"auto-upgrade":           "control plane to 1.31.2-gke.300 in the window starting 2026-10-16 03:00 UTC",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+
+	// An exclusion over the next window holds the upgrade back
+	cluster.MaintenancePolicy.Window.MaintenanceExclusions = map[string]gkeTimeWindow{
+		"holiday-freeze": {StartTime: "2026-10-15T18:00:00Z", EndTime: "2026-10-20T00:00:00Z"},
+	}
+	if got := gkeUpgradeComponents(&cluster, &config, gkeTestNow)["auto-upgrade"]; got != "control plane to 1.31.2-gke.300, held by exclusion holiday-freeze until 2026-10-20 00:00 UTC" {
+		t.Errorf("auto-upgrade with exclusion = %q", got)
+	}
+
+	// Stable's default is older than the cluster, so nothing is queued
+	cluster.ReleaseChannel.Channel = "STABLE"
+	if got, ok := gkeUpgradeComponents(&cluster, &config, gkeTestNow)["auto-upgrade"]; ok {
+		t.Errorf("auto-upgrade on stable = %q", got)
+	}
+
+	autopilot := gkeClusterInfo{CurrentMasterVersion: "1.30.1-gke.1"}
+	autopilot.Autopilot.Enabled = true
+	got = gkeUpgradeComponents(&autopilot, nil, gkeTestNow)
+	if got["autopilot"] != "enabled" || got["release-channel"] != "none" || got["node-auto-provisioning"] != "" {
+		t.Errorf("autopilot components = %v", got)
+	}
+}
+
+func TestCompareGKEVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.30.5-gke.100", "1.30.5-gke.100", 0},
+		{"1.30.5-gke.100", "1.30.5-gke.1014001", -1},
+		{"1.31.0-gke.1", "1.30.9-gke.9999", 1},
+		{"1.30", "1.30.1-gke.1", -1},
+	}
+	for _, tt := range tests {
+		got := compareGKEVersions(tt.a, tt.b)
+		if (got < 0) != (tt.want < 0) || (got > 0) != (tt.want > 0) {
+			t.Errorf("compareGKEVersions(%q, %q) = %d, want sign of %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 )
@@ -424,3 +425,71 @@ func TestGKECreateOptionsGCPFields(t *testing.T) {
 		t.Error("expected preemptible to be true")
 	}
 }
+
+func TestGKECreateArgs(t *testing.T) {
+	args, err := gkeCreateArgs(CreateOptions{
+		Name:              "prod",
+		WorkerCount:       3,
+		WorkerType:        "e2-standard-4",
+		GKEReleaseChannel: "Stable",
+		GKEAutoProvisioning: &GKEAutoProvisioning{
+			MaxCPU:      64,
+			MaxMemoryGB: 256,
+		},
+	}, "us-central1")
+	if err != nil {
+		t.Fatalf("gkeCreateArgs: %v", err)
+	}
+	got := strings.Join(args, " ")
+	want := "container clusters create prod --region us-central1 --num-nodes 3 --machine-type e2-standard-4 " +
+		"--enable-autoprovisioning --max-cpu 64 --max-memory 256 --release-channel stable"
+	if got != want {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+
+	args, err = gkeCreateArgs(CreateOptions{Name: "auto", WorkerCount: 3, WorkerType: "e2-standard-4", GKEAutopilot: true, GKEReleaseChannel: "regular"}, "europe-west1")
+	if err != nil {
+		t.Fatalf("gkeCreateArgs autopilot: %v", err)
+	}
+	if got := strings.Join(args, " "); got != "container clusters create-auto auto --region europe-west1 --release-channel regular" {
+		t.Errorf("autopilot args = %q", got)
+	}
+}
+
+func TestGKECreateArgsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts CreateOptions
+		want string
+	}{
+		{"unknown channel", CreateOptions{Name: "c", GKEReleaseChannel: "beta"}, "unknown release channel"},
+		{"autopilot preemptible", CreateOptions{Name: "c", GKEAutopilot: true, Preemptible: true}, "Spot Pods"},
+		{"autopilot autoprovisioning", CreateOptions{Name: "c", GKEAutopilot: true, GKEAutoProvisioning: &GKEAutoProvisioning{MaxCPU: 1, MaxMemoryGB: 1}}, "always auto-provision"},
+		{"autopilot without channel", CreateOptions{Name: "c", GKEAutopilot: true, GKEReleaseChannel: "none"}, "release channel"},
+		{"autoprovisioning without limits", CreateOptions{Name: "c", GKEAutoProvisioning: &GKEAutoProvisioning{MaxCPU: 8}}, "maximum CPU and memory"},
+		{"autoprovisioning minimum over maximum", CreateOptions{Name: "c", GKEAutoProvisioning: &GKEAutoProvisioning{MinCPU: 16, MaxCPU: 8, MaxMemoryGB: 32}}, "minimums"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGKECreateOptions(tt.opts)
+			if _, ok := err.(*ErrInvalidConfiguration); !ok || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want ErrInvalidConfiguration containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGKEProviderUpdateValidation(t *testing.T) {
+	provider := NewGKEProvider(GKEProviderOptions{ProjectID: "test-project", Region: "us-central1"})
+	ctx := context.Background()
+
+	if err := provider.SetReleaseChannel(ctx, "prod", "nightly"); err == nil || !strings.Contains(err.Error(), "unknown release channel") {
+		t.Errorf("SetReleaseChannel err = %v", err)
+	}
+	if err := provider.SetNodeAutoProvisioning(ctx, "prod", &GKEAutoProvisioning{MaxCPU: 8}); err == nil || !strings.Contains(err.Error(), "maximum CPU and memory") {
+		t.Errorf("SetNodeAutoProvisioning err = %v", err)
+	}
+	if err := provider.SetNodeAutoProvisioning(ctx, "", nil); err == nil {
+		t.Error("expected error for empty cluster name")
+	}
+}
@@ -1,29 +1,30 @@
 package cluster
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 )
 
-// KubeadmProvider manages kubeadm-based Kubernetes clusters on EC2
+// KubeadmProvider manages kubeadm-based Kubernetes clusters. The machines
+// come from an InstanceBackend (EC2 by default, or Hetzner Cloud); kubeadm
+// itself runs over SSH.
 type KubeadmProvider struct {
-	awsProfile  string
-	region      string
-	vpcID       string
-	subnetID    string
-	keyPairName string
-	sshKeyPath  string
-	debug       bool
+	backend    InstanceBackend
+	sshKeyPath string
+	sshUser    string
+	jumpHost   string
+	pool       *SSHPool
+	debug      bool
 }
 
-// KubeadmProviderOptions contains options for creating a kubeadm provider
+// KubeadmProviderOptions contains options for creating a kubeadm provider.
+// The AWS fields configure the default EC2 backend and are ignored when
+// Backend is set.
 type KubeadmProviderOptions struct {
 	AWSProfile  string
 	Region      string
@@ -31,7 +32,15 @@ type KubeadmProviderOptions struct {
 	SubnetID    string
 	KeyPairName string
 	SSHKeyPath  string
-	Debug       bool
+	// SSHUser overrides the backend's login user (ubuntu on EC2, root on
+	// Hetzner) for images with a different default account
+	SSHUser string
+	// JumpHost is a bastion as [user@]host[:port]. Nodes are then reached
+	// on their private addresses through it, so clusters in private
+	// subnets can be managed.
+	JumpHost string
+	Backend  InstanceBackend
+	Debug    bool
 }
 
 // NewKubeadmProvider creates a new kubeadm cluster provider
@@ -42,23 +51,46 @@ func NewKubeadmProvider(opts KubeadmProviderOptions) *KubeadmProvider {
 		sshKeyPath = filepath.Join(home, ".ssh", "id_rsa")
 	}
 
+	backend := opts.Backend
+	if backend == nil {
+		backend = &ec2Backend{
+			awsProfile:  opts.AWSProfile,
+			region:      opts.Region,
+			vpcID:       opts.VPCID,
+			subnetID:    opts.SubnetID,
+			keyPairName: opts.KeyPairName,
+			debug:       opts.Debug,
+		}
+	}
+
 	return &KubeadmProvider{
-		awsProfile:  opts.AWSProfile,
-		region:      opts.Region,
-		vpcID:       opts.VPCID,
-		subnetID:    opts.SubnetID,
-		keyPairName: opts.KeyPairName,
-		sshKeyPath:  sshKeyPath,
-		debug:       opts.Debug,
+		backend:    backend,
+		sshKeyPath: sshKeyPath,
+		sshUser:    opts.SSHUser,
+		jumpHost:   opts.JumpHost,
+		pool:       NewSSHPool(DefaultSSHIdleTimeout),
+		debug:      opts.Debug,
 	}
 }
 
+// Backend returns the instance backend the provider launches nodes on
+func (p *KubeadmProvider) Backend() InstanceBackend {
+	return p.backend
+}
+
 // Type returns the cluster type
 func (p *KubeadmProvider) Type() ClusterType {
 	return ClusterTypeKubeadm
 }
 
-// Create provisions a new kubeadm cluster on EC2
+// Create provisions a new kubeadm cluster on the provider's instance backend.
+// Progress is saved after every step; if creation fails, the instances are
+// kept and Resume continues from the last completed step.
+//
+// With ControlPlaneCount above one the cluster is highly available: an
+// HAProxy node load-balances the API servers, kubeadm init uses it as the
+// control plane endpoint, and the extra control plane nodes join with the
+// uploaded certificates.
 func (p *KubeadmProvider) Create(ctx context.Context, opts CreateOptions) (*ClusterInfo, error) {
 	if opts.Name == "" {
 		return nil, &ErrInvalidConfiguration{Message: "cluster name is required"}
@@ -66,14 +98,31 @@ func (p *KubeadmProvider) Create(ctx context.Context, opts CreateOptions) (*Clus
 
 	region := opts.Region
 	if region == "" {
-		region = p.region
+		region = p.backend.Region()
 	}
 	if region == "" {
 		return nil, &ErrInvalidConfiguration{Message: "region is required"}
 	}
 
-	if p.keyPairName == "" {
-		return nil, &ErrInvalidConfiguration{Message: "SSH key pair name is required for kubeadm clusters"}
+	controlPlaneCount := opts.ControlPlaneCount
+	if controlPlaneCount <= 0 {
+		controlPlaneCount = 1
+	}
+	if controlPlaneCount%2 == 0 {
+		return nil, &ErrInvalidConfiguration{Message: fmt.Sprintf("control plane count must be odd to keep etcd quorum, got %d", controlPlaneCount)}
+	}
+
+	if err := p.backend.Validate(); err != nil {
+		return nil, err
+	}
+
+	// An interrupted creation has to be resumed or deleted first
+	state, err := LoadKubeadmCreateState(opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		return nil, fmt.Errorf("creation of cluster %s was interrupted after step %q; resume it or delete the cluster first", opts.Name, state.Step)
 	}
 
 	// Check if cluster already exists
@@ -82,281 +131,597 @@ func (p *KubeadmProvider) Create(ctx context.Context, opts CreateOptions) (*Clus
 		return nil, &ErrClusterExists{ClusterName: opts.Name}
 	}
 
-	if p.debug {
-		fmt.Printf("[kubeadm] creating cluster %s in %s\n", opts.Name, region)
+	workerCount := opts.WorkerCount
+	if workerCount <= 0 {
+		workerCount = 2
 	}
 
-	// Step 1: Create security group
-	sgID, err := p.createSecurityGroup(ctx, opts.Name)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create security group: %w", err)
+	now := time.Now().UTC()
+	state = &KubeadmCreateState{
+		ClusterName:       opts.Name,
+		Backend:           p.backend.Name(),
+		Region:            region,
+		Step:              KubeadmStepStarted,
+		KubernetesVersion: opts.KubernetesVersion,
+		ControlPlaneCount: controlPlaneCount,
+		ControlPlaneType:  opts.ControlPlaneType,
+		WorkerCount:       workerCount,
+		WorkerType:        opts.WorkerType,
+		Tags:              opts.Tags,
+		CreatedAt:         now,
 	}
 
-	if p.debug {
-		fmt.Printf("[kubeadm] created security group: %s\n", sgID)
-	}
+	kubeadmLog.Debugf("creating cluster %s in %s on %s", opts.Name, region, p.backend.Name())
 
-	// Step 2: Launch control plane instance
-	cpInstanceType := opts.ControlPlaneType
-	if cpInstanceType == "" {
-		cpInstanceType = "t3.medium"
+	return p.runCreate(ctx, state)
+}
+
+// Resume continues a creation that failed or was interrupted, skipping the
+// steps its saved state records as done
+func (p *KubeadmProvider) Resume(ctx context.Context, clusterName string) (*ClusterInfo, error) {
+	if clusterName == "" {
+		return nil, &ErrInvalidConfiguration{Message: "cluster name is required"}
 	}
 
-	cpInstance, err := p.launchInstance(ctx, opts.Name, "control-plane", cpInstanceType, sgID, opts.Tags)
+	state, err := LoadKubeadmCreateState(clusterName)
 	if err != nil {
-		_ = p.deleteSecurityGroup(ctx, sgID)
-		return nil, fmt.Errorf("failed to launch control plane: %w", err)
+		return nil, err
 	}
-
-	if p.debug {
-		fmt.Printf("[kubeadm] launched control plane instance: %s (%s)\n", cpInstance.InstanceID, cpInstance.PublicIP)
+	if state == nil {
+		return nil, fmt.Errorf("no interrupted creation found for cluster %s", clusterName)
+	}
+	if state.Backend != p.backend.Name() {
+		return nil, &ErrInvalidConfiguration{Message: fmt.Sprintf("cluster %s was created on %s, not %s", clusterName, state.Backend, p.backend.Name())}
 	}
 
-	// Wait for SSH to be available
-	if err := WaitForSSH(ctx, cpInstance.PublicIP, 22, DefaultSSHConnectTimeout); err != nil {
-		_ = p.terminateInstance(ctx, cpInstance.InstanceID)
-		_ = p.deleteSecurityGroup(ctx, sgID)
-		return nil, fmt.Errorf("control plane SSH not available: %w", err)
+	if err := p.backend.Validate(); err != nil {
+		return nil, err
 	}
 
-	// Step 3: Bootstrap control plane
-	ssh, err := NewSSHClient(SSHClientOptions{
-		Host:           cpInstance.PublicIP,
-		User:           "ubuntu",
-		PrivateKeyPath: p.sshKeyPath,
-		Debug:          p.debug,
-	})
-	if err != nil {
-		_ = p.terminateInstance(ctx, cpInstance.InstanceID)
-		_ = p.deleteSecurityGroup(ctx, sgID)
-		return nil, fmt.Errorf("failed to create SSH client: %w", err)
+	kubeadmLog.Debugf("resuming cluster %s after step %s", clusterName, state.Step)
+
+	return p.runCreate(ctx, state)
+}
+
+// runCreate drives creation from state.Step to completion, saving the state
+// after every step
+func (p *KubeadmProvider) runCreate(ctx context.Context, state *KubeadmCreateState) (*ClusterInfo, error) {
+	advance := func(step KubeadmCreateStep) error {
+		state.Step = step
+		state.LastError = ""
+		if err := SaveKubeadmCreateState(state); err != nil {
+			return fmt.Errorf("failed to save creation state: %w", err)
+		}
+		return nil
+	}
+	fail := func(err error) (*ClusterInfo, error) {
+		state.LastError = err.Error()
+		if saveErr := SaveKubeadmCreateState(state); saveErr != nil {
+			return nil, fmt.Errorf("%w (and failed to save creation state: %v)", err, saveErr)
+		}
+		return nil, fmt.Errorf("%w (progress saved after step %q; resume to continue)", err, state.Step)
 	}
 
-	if err := ssh.Connect(ctx); err != nil {
-		_ = p.terminateInstance(ctx, cpInstance.InstanceID)
-		_ = p.deleteSecurityGroup(ctx, sgID)
-		return nil, fmt.Errorf("failed to connect to control plane: %w", err)
+	if err := advance(state.Step); err != nil {
+		return nil, err
 	}
-	defer ssh.Close()
 
-	// Bootstrap the node
-	bootstrapConfig := DefaultBootstrapConfig()
-	bootstrapConfig.ClusterName = opts.Name
-	if opts.KubernetesVersion != "" {
-		bootstrapConfig.KubernetesVersion = opts.KubernetesVersion
+	// Step 1: Create the cluster network and firewall
+	if !state.Reached(KubeadmStepNetworkPrepared) {
+		network, err := p.backend.PrepareCluster(ctx, state.ClusterName)
+		if err != nil {
+			return fail(fmt.Errorf("failed to prepare cluster network: %w", err))
+		}
+		state.Network = network
+		if err := advance(KubeadmStepNetworkPrepared); err != nil {
+			return nil, err
+		}
+
+		kubeadmLog.Debugf("prepared cluster network: %s", network)
 	}
 
-	if p.debug {
-		fmt.Println("[kubeadm] bootstrapping control plane node...")
+	// Instances recorded by an earlier run may have been deleted since
+	live := make(map[string]Instance)
+	if state.ControlPlane != nil || state.LoadBalancer != nil || len(state.ControlPlanes) > 0 || len(state.Workers) > 0 {
+		instances, err := p.backend.ListInstances(ctx, state.ClusterName)
+		if err != nil {
+			return fail(fmt.Errorf("failed to find cluster instances: %w", err))
+		}
+		for _, inst := range instances {
+			live[inst.ID] = inst
+		}
 	}
 
-	if err := BootstrapNode(ctx, ssh, bootstrapConfig); err != nil {
-		_ = p.terminateInstance(ctx, cpInstance.InstanceID)
-		_ = p.deleteSecurityGroup(ctx, sgID)
-		return nil, fmt.Errorf("failed to bootstrap control plane: %w", err)
+	cpInstanceType := state.ControlPlaneType
+	if cpInstanceType == "" {
+		cpInstanceType = p.backend.DefaultInstanceType()
 	}
 
-	// Initialize the control plane
-	if p.debug {
-		fmt.Println("[kubeadm] initializing control plane...")
+	// Step 2: Launch the API server load balancer of an HA cluster. Its
+	// address is baked into the certificates, so it cannot be replaced
+	// once the control plane is initialized.
+	if state.HA() {
+		if state.LoadBalancer != nil {
+			if _, ok := live[state.LoadBalancer.ID]; !ok {
+				if state.Reached(KubeadmStepControlPlaneInitialized) {
+					return fail(fmt.Errorf("load balancer %s was deleted after the control plane was initialized; delete and recreate the cluster", state.LoadBalancer.ID))
+				}
+				state.LoadBalancer = nil
+			}
+		}
+		if state.LoadBalancer == nil {
+			lb, err := p.backend.Launch(ctx, LaunchRequest{
+				ClusterName:  state.ClusterName,
+				Role:         "lb",
+				InstanceType: cpInstanceType,
+				Network:      state.Network,
+				Tags:         state.Tags,
+			})
+			if err != nil {
+				return fail(fmt.Errorf("failed to launch load balancer: %w", err))
+			}
+			state.LoadBalancer = lb
+			if err := advance(state.Step); err != nil {
+				return nil, err
+			}
+
+			kubeadmLog.Debugf("launched load balancer instance: %s (%s)", lb.ID, lb.PublicIP)
+		}
 	}
 
-	initOutput, err := InitializeControlPlane(ctx, ssh, bootstrapConfig)
-	if err != nil {
-		_ = p.terminateInstance(ctx, cpInstance.InstanceID)
-		_ = p.deleteSecurityGroup(ctx, sgID)
-		return nil, fmt.Errorf("kubeadm init failed: %w", err)
+	// Step 3: Launch control plane instance
+	if state.ControlPlane != nil {
+		if _, ok := live[state.ControlPlane.ID]; !ok {
+			kubeadmLog.Debugf("control plane %s is gone, relaunching", state.ControlPlane.ID)
+			state.ControlPlane = nil
+			state.Step = KubeadmStepNetworkPrepared
+			// Other nodes joined the old control plane and must join again
+			for i := range state.ControlPlanes {
+				state.ControlPlanes[i].Joined = false
+			}
+			for i := range state.Workers {
+				state.Workers[i].Joined = false
+			}
+		}
 	}
+	resumedControlPlane := state.ControlPlane != nil
+	if state.ControlPlane == nil {
+		cpInstance, err := p.backend.Launch(ctx, LaunchRequest{
+			ClusterName:  state.ClusterName,
+			Role:         "control-plane",
+			InstanceType: cpInstanceType,
+			Network:      state.Network,
+			Tags:         state.Tags,
+		})
+		if err != nil {
+			return fail(fmt.Errorf("failed to launch control plane: %w", err))
+		}
+		state.ControlPlane = cpInstance
+		if err := advance(KubeadmStepControlPlaneLaunched); err != nil {
+			return nil, err
+		}
 
-	// Install CNI
-	if p.debug {
-		fmt.Println("[kubeadm] installing CNI (Calico)...")
+		kubeadmLog.Debugf("launched control plane instance: %s (%s)", cpInstance.ID, cpInstance.PublicIP)
 	}
+	cpInstance := state.ControlPlane
 
-	if err := InstallCNI(ctx, ssh, bootstrapConfig.CNI); err != nil {
-		_ = p.terminateInstance(ctx, cpInstance.InstanceID)
-		_ = p.deleteSecurityGroup(ctx, sgID)
-		return nil, fmt.Errorf("failed to install CNI: %w", err)
+	bootstrapConfig := DefaultBootstrapConfig()
+	bootstrapConfig.ClusterName = state.ClusterName
+	if state.KubernetesVersion != "" {
+		bootstrapConfig.KubernetesVersion = state.KubernetesVersion
 	}
+	privateCIDR := p.backend.PrivateNetworkCIDR()
 
-	// Step 4: Launch worker nodes
-	workerCount := opts.WorkerCount
-	if workerCount <= 0 {
-		workerCount = 2
+	if state.HA() {
+		bootstrapConfig.ControlPlaneEndpoint = state.LoadBalancer.nodeAddress() + ":6443"
+		if err := p.configureLoadBalancer(ctx, state); err != nil {
+			return fail(err)
+		}
 	}
 
-	workerType := opts.WorkerType
-	if workerType == "" {
-		workerType = "t3.medium"
+	// Step 4: Bootstrap control plane
+	ssh, err := p.connect(ctx, cpInstance)
+	if err != nil {
+		return fail(fmt.Errorf("control plane: %w", err))
 	}
+	defer ssh.Close()
 
-	var workerNodes []NodeInfo
+	var joinOutput *KubeadmInitOutput
+	var certificateKey string
+	if !state.Reached(KubeadmStepControlPlaneInitialized) {
+		kubeadmLog.Debug("bootstrapping control plane node...")
 
-	for i := 0; i < workerCount; i++ {
-		workerInstance, err := p.launchInstance(ctx, opts.Name, fmt.Sprintf("worker-%d", i), workerType, sgID, opts.Tags)
-		if err != nil {
-			// Clean up on failure
-			for _, w := range workerNodes {
-				_ = p.terminateInstance(ctx, w.InternalIP) // Using InternalIP to store instance ID temporarily
+		if err := BootstrapNode(ctx, ssh, bootstrapConfig); err != nil {
+			return fail(fmt.Errorf("failed to bootstrap control plane: %w", err))
+		}
+
+		// A previous kubeadm init may have stopped halfway
+		if resumedControlPlane {
+			if err := ResetNode(ctx, ssh); err != nil {
+				kubeadmLog.Debugf("warning: kubeadm reset: %v", err)
 			}
-			_ = p.terminateInstance(ctx, cpInstance.InstanceID)
-			_ = p.deleteSecurityGroup(ctx, sgID)
-			return nil, fmt.Errorf("failed to launch worker %d: %w", i, err)
 		}
 
-		if p.debug {
-			fmt.Printf("[kubeadm] launched worker instance %d: %s (%s)\n", i, workerInstance.InstanceID, workerInstance.PublicIP)
+		// Initialize the control plane
+		kubeadmLog.Debug("initializing control plane...")
+
+		cpConfig := bootstrapConfig
+		if privateCIDR != "" {
+			cpConfig.NodeIP = cpInstance.PrivateIP
+			cpConfig.APIServerSANs = []string{cpInstance.PublicIP, cpInstance.PrivateIP}
+		}
+		if state.HA() {
+			certificateKey, err = GenerateCertificateKey()
+			if err != nil {
+				return fail(err)
+			}
+			cpConfig.CertificateKey = certificateKey
+		}
+		joinOutput, err = InitializeControlPlane(ctx, ssh, cpConfig)
+		if err != nil {
+			return fail(fmt.Errorf("kubeadm init failed: %w", err))
 		}
+		if err := advance(KubeadmStepControlPlaneInitialized); err != nil {
+			return nil, err
+		}
+	}
+
+	// Install CNI
+	if !state.Reached(KubeadmStepCNIInstalled) {
+		kubeadmLog.Debug("installing CNI (Calico)...")
 
-		// Wait for SSH
-		if err := WaitForSSH(ctx, workerInstance.PublicIP, 22, DefaultSSHConnectTimeout); err != nil {
-			// Continue anyway, will fail on bootstrap
-			if p.debug {
-				fmt.Printf("[kubeadm] warning: worker %d SSH not available: %v\n", i, err)
+		if err := InstallCNI(ctx, ssh, bootstrapConfig.CNI); err != nil {
+			return fail(fmt.Errorf("failed to install CNI: %w", err))
+		}
+		if privateCIDR != "" {
+			if err := PinCalicoNodeAddresses(ctx, ssh, privateCIDR); err != nil {
+				return fail(err)
 			}
 		}
+		if err := advance(KubeadmStepCNIInstalled); err != nil {
+			return nil, err
+		}
+	}
 
-		// Bootstrap and join worker
-		workerSSH, err := NewSSHClient(SSHClientOptions{
-			Host:           workerInstance.PublicIP,
-			User:           "ubuntu",
-			PrivateKeyPath: p.sshKeyPath,
-			Debug:          p.debug,
-		})
+	// Join tokens are not saved; a resumed run asks the control plane
+	if joinOutput == nil {
+		joinOutput, err = GetJoinToken(ctx, ssh)
 		if err != nil {
-			continue
+			return fail(fmt.Errorf("failed to get join token: %w", err))
 		}
+	}
+
+	var failed []string
+
+	// Step 5: Launch and join additional control plane nodes
+	controlPlaneNodes := []NodeInfo{{
+		Name:       fmt.Sprintf("%s-control-plane", state.ClusterName),
+		Role:       "control-plane",
+		Status:     "Ready",
+		InternalIP: cpInstance.PrivateIP,
+		ExternalIP: cpInstance.PublicIP,
+	}}
+	for i := 1; i < state.ControlPlaneCount; i++ {
+		role := fmt.Sprintf("control-plane-%d", i)
 
-		if err := workerSSH.Connect(ctx); err != nil {
-			workerSSH.Close()
+		node, err := p.ensureNode(ctx, state, live, role, cpInstanceType, &state.ControlPlanes)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: launch: %v", role, err))
 			continue
 		}
 
-		if p.debug {
-			fmt.Printf("[kubeadm] bootstrapping worker %d...\n", i)
-		}
+		if !node.Joined {
+			// The certificates uploaded by kubeadm init expire after two
+			// hours; resumed runs upload them again under a new key
+			if certificateKey == "" {
+				if certificateKey, err = GenerateCertificateKey(); err != nil {
+					return fail(err)
+				}
+				if err := UploadCertificates(ctx, ssh, certificateKey); err != nil {
+					return fail(err)
+				}
+			}
 
-		if err := BootstrapNode(ctx, workerSSH, bootstrapConfig); err != nil {
-			workerSSH.Close()
-			if p.debug {
-				fmt.Printf("[kubeadm] warning: failed to bootstrap worker %d: %v\n", i, err)
+			joinConfig := bootstrapConfig
+			joinConfig.IsControlPlane = true
+			joinConfig.CertificateKey = certificateKey
+			if err := p.joinNode(ctx, &node.Instance, joinConfig, joinOutput, cpInstance.PrivateIP); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", role, err))
+				continue
+			}
+			node.Joined = true
+			if err := advance(state.Step); err != nil {
+				return nil, err
 			}
-			continue
 		}
 
-		// Join the worker to the cluster
-		joinConfig := bootstrapConfig
-		joinConfig.ControlPlaneIP = cpInstance.PrivateIP
-		joinConfig.JoinToken = initOutput.Token
-		joinConfig.CACertHash = initOutput.CACertHash
-		joinConfig.IsControlPlane = false
+		controlPlaneNodes = append(controlPlaneNodes, NodeInfo{
+			Name:       node.Instance.Name,
+			Role:       "control-plane",
+			Status:     "Ready",
+			InternalIP: node.Instance.PrivateIP,
+			ExternalIP: node.Instance.PublicIP,
+		})
+	}
 
-		if p.debug {
-			fmt.Printf("[kubeadm] joining worker %d to cluster...\n", i)
+	// Spread API traffic over every control plane node that joined
+	if state.HA() && len(controlPlaneNodes) > 1 {
+		if err := p.configureLoadBalancer(ctx, state); err != nil {
+			return fail(err)
 		}
+	}
 
-		if err := JoinWorker(ctx, workerSSH, joinConfig); err != nil {
-			workerSSH.Close()
-			if p.debug {
-				fmt.Printf("[kubeadm] warning: failed to join worker %d: %v\n", i, err)
-			}
+	// Step 6: Launch worker nodes
+	workerType := state.WorkerType
+	if workerType == "" {
+		workerType = p.backend.DefaultInstanceType()
+	}
+
+	var workerNodes []NodeInfo
+
+	for i := 0; i < state.WorkerCount; i++ {
+		role := fmt.Sprintf("worker-%d", i)
+
+		worker, err := p.ensureNode(ctx, state, live, role, workerType, &state.Workers)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: launch: %v", role, err))
 			continue
 		}
 
-		workerSSH.Close()
+		if !worker.Joined {
+			if err := p.joinNode(ctx, &worker.Instance, bootstrapConfig, joinOutput, cpInstance.PrivateIP); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", role, err))
+				continue
+			}
+			worker.Joined = true
+			if err := advance(state.Step); err != nil {
+				return nil, err
+			}
+		}
 
 		workerNodes = append(workerNodes, NodeInfo{
-			Name:       fmt.Sprintf("%s-worker-%d", opts.Name, i),
+			Name:       fmt.Sprintf("%s-worker-%d", state.ClusterName, i),
 			Role:       "worker",
 			Status:     "Ready",
-			InternalIP: workerInstance.PrivateIP,
-			ExternalIP: workerInstance.PublicIP,
+			InternalIP: worker.Instance.PrivateIP,
+			ExternalIP: worker.Instance.PublicIP,
 		})
 	}
 
-	// Wait for all nodes to be ready
-	if p.debug {
-		fmt.Println("[kubeadm] waiting for nodes to be ready...")
+	if len(failed) > 0 {
+		return fail(fmt.Errorf("%d node(s) did not join: %s", len(failed), strings.Join(failed, "; ")))
 	}
 
+	// Wait for all nodes to be ready
+	kubeadmLog.Debug("waiting for nodes to be ready...")
+
 	if err := WaitForNodeReady(ctx, ssh, DefaultSSHConnectTimeout); err != nil {
-		if p.debug {
-			fmt.Printf("[kubeadm] warning: not all nodes ready: %v\n", err)
-		}
+		kubeadmLog.Debugf("warning: not all nodes ready: %v", err)
+	}
+
+	// The cluster is complete; nothing is left to resume
+	if err := DeleteKubeadmCreateState(state.ClusterName); err != nil {
+		kubeadmLog.Debugf("warning: failed to remove creation state: %v", err)
+	}
+
+	endpoint := cpInstance.nodeAddress()
+	if state.HA() {
+		endpoint = state.LoadBalancer.nodeAddress()
 	}
 
 	// Build cluster info
 	info := &ClusterInfo{
-		Name:              opts.Name,
+		Name:              state.ClusterName,
 		Type:              ClusterTypeKubeadm,
 		Status:            "ACTIVE",
 		KubernetesVersion: bootstrapConfig.KubernetesVersion,
-		Endpoint:          fmt.Sprintf("https://%s:6443", cpInstance.PublicIP),
-		Region:            region,
-		ControlPlaneNodes: []NodeInfo{
-			{
-				Name:       fmt.Sprintf("%s-control-plane", opts.Name),
-				Role:       "control-plane",
-				Status:     "Ready",
-				InternalIP: cpInstance.PrivateIP,
-				ExternalIP: cpInstance.PublicIP,
-			},
-		},
-		WorkerNodes: workerNodes,
-		CreatedAt:   time.Now(),
+		Endpoint:          fmt.Sprintf("https://%s:6443", endpoint),
+		Region:            state.Region,
+		ControlPlaneNodes: controlPlaneNodes,
+		WorkerNodes:       workerNodes,
+		CreatedAt:         state.CreatedAt,
 	}
 
-	if p.debug {
-		fmt.Printf("[kubeadm] cluster %s created successfully\n", opts.Name)
-	}
+	kubeadmLog.Debugf("cluster %s created successfully", state.ClusterName)
 
 	return info, nil
 }
 
+// ensureNode returns the tracked node for role, launching it (again) when
+// it was never launched or its instance is gone
+func (p *KubeadmProvider) ensureNode(ctx context.Context, state *KubeadmCreateState, live map[string]Instance, role, instanceType string, nodes *[]KubeadmNodeState) (*KubeadmNodeState, error) {
+	if node := state.node(role); node != nil {
+		if _, ok := live[node.Instance.ID]; ok {
+			return node, nil
+		}
+		kubeadmLog.Debugf("%s instance %s is gone, relaunching", role, node.Instance.ID)
+		state.dropNode(role)
+	}
+
+	instance, err := p.backend.Launch(ctx, LaunchRequest{
+		ClusterName:  state.ClusterName,
+		Role:         role,
+		InstanceType: instanceType,
+		Network:      state.Network,
+		Tags:         state.Tags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	*nodes = append(*nodes, KubeadmNodeState{Instance: *instance})
+	if err := SaveKubeadmCreateState(state); err != nil {
+		return nil, fmt.Errorf("failed to save creation state: %w", err)
+	}
+
+	kubeadmLog.Debugf("launched %s instance: %s (%s)", role, instance.ID, instance.PublicIP)
+
+	return state.node(role), nil
+}
+
+// configureLoadBalancer points the HA load balancer at the first control
+// plane node and every additional one that has joined
+func (p *KubeadmProvider) configureLoadBalancer(ctx context.Context, state *KubeadmCreateState) error {
+	apiServers := []string{state.ControlPlane.PrivateIP}
+	for _, cp := range state.ControlPlanes {
+		if cp.Joined {
+			apiServers = append(apiServers, cp.Instance.PrivateIP)
+		}
+	}
+
+	kubeadmLog.Debugf("configuring load balancer for %s", strings.Join(apiServers, ", "))
+
+	ssh, err := p.connect(ctx, state.LoadBalancer)
+	if err != nil {
+		return fmt.Errorf("load balancer: %w", err)
+	}
+	defer ssh.Close()
+
+	return ConfigureLoadBalancer(ctx, ssh, apiServers)
+}
+
+// connect waits for SSH on an instance and opens a session
+func (p *KubeadmProvider) connect(ctx context.Context, inst *Instance) (*SSHClient, error) {
+	addr := p.sshAddress(inst.PublicIP, inst.PrivateIP)
+	if p.jumpHost == "" {
+		if err := WaitForSSH(ctx, addr, 22, DefaultSSHConnectTimeout); err != nil {
+			return nil, fmt.Errorf("SSH not available: %w", err)
+		}
+		ssh, err := p.dial(ctx, addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		return ssh, nil
+	}
+
+	// A node behind the jump host cannot be probed directly, so the whole
+	// connection is retried until the node's sshd answers
+	if _, err := NewSSHClient(p.sshOptions(addr)); err != nil {
+		return nil, fmt.Errorf("failed to create SSH client: %w", err)
+	}
+	deadline := time.Now().Add(DefaultSSHConnectTimeout)
+	for {
+		ssh, err := p.dial(ctx, addr)
+		if err == nil {
+			return ssh, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("SSH not available via %s: %w", p.jumpHost, err)
+		}
+		kubeadmLog.Debugf("waiting for SSH on %s via %s: %v", addr, p.jumpHost, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// dial opens a pooled SSH session to a node; Close hands it back to the
+// pool so later steps on the same node reuse the connection
+func (p *KubeadmProvider) dial(ctx context.Context, host string) (*SSHClient, error) {
+	return p.pool.Get(ctx, p.sshOptions(host))
+}
+
+func (p *KubeadmProvider) sshOptions(host string) SSHClientOptions {
+	user := p.sshUser
+	if user == "" {
+		user = p.backend.SSHUser()
+	}
+	return SSHClientOptions{
+		Host:           host,
+		User:           user,
+		PrivateKeyPath: p.sshKeyPath,
+		JumpHost:       p.jumpHost,
+		Debug:          p.debug,
+	}
+}
+
+// sshAddress picks the address to SSH to: the public one when nodes are
+// reached directly, the private one behind a jump host
+func (p *KubeadmProvider) sshAddress(publicIP, privateIP string) string {
+	if p.jumpHost != "" && privateIP != "" {
+		return privateIP
+	}
+	if publicIP != "" {
+		return publicIP
+	}
+	return privateIP
+}
+
+// joinNode bootstraps a launched node and joins it to the cluster, as a
+// control plane node when config.IsControlPlane is set
+func (p *KubeadmProvider) joinNode(ctx context.Context, node *Instance, config BootstrapConfig, join *KubeadmInitOutput, controlPlaneIP string) error {
+	nodeSSH, err := p.connect(ctx, node)
+	if err != nil {
+		return err
+	}
+	defer nodeSSH.Close()
+
+	kubeadmLog.Debugf("bootstrapping %s...", node.Name)
+	if err := BootstrapNode(ctx, nodeSSH, config); err != nil {
+		return fmt.Errorf("failed to bootstrap: %w", err)
+	}
+
+	// A previous join attempt may have stopped halfway
+	if err := ResetNode(ctx, nodeSSH); err != nil {
+		kubeadmLog.Debugf("warning: kubeadm reset on %s: %v", node.Name, err)
+	}
+
+	joinConfig := config
+	joinConfig.ControlPlaneIP = controlPlaneIP
+	joinConfig.JoinToken = join.Token
+	joinConfig.CACertHash = join.CACertHash
+	if p.backend.PrivateNetworkCIDR() != "" {
+		joinConfig.NodeIP = node.PrivateIP
+	}
+
+	kubeadmLog.Debugf("joining %s to cluster...", node.Name)
+	if joinConfig.IsControlPlane {
+		return JoinControlPlane(ctx, nodeSSH, joinConfig)
+	}
+	if err := JoinWorker(ctx, nodeSSH, joinConfig); err != nil {
+		return fmt.Errorf("failed to join: %w", err)
+	}
+	return nil
+}
+
 // Delete removes a kubeadm cluster
 func (p *KubeadmProvider) Delete(ctx context.Context, clusterName string) error {
 	if clusterName == "" {
 		return &ErrInvalidConfiguration{Message: "cluster name is required"}
 	}
 
-	if p.debug {
-		fmt.Printf("[kubeadm] deleting cluster %s\n", clusterName)
-	}
+	kubeadmLog.Debugf("deleting cluster %s", clusterName)
 
-	// Find all instances with the cluster tag
-	instances, err := p.findClusterInstances(ctx, clusterName)
+	// Find all instances of the cluster
+	instances, err := p.backend.ListInstances(ctx, clusterName)
 	if err != nil {
 		return fmt.Errorf("failed to find cluster instances: %w", err)
 	}
 
 	// Terminate all instances
-	for _, instanceID := range instances {
-		if p.debug {
-			fmt.Printf("[kubeadm] terminating instance %s\n", instanceID)
-		}
-		if err := p.terminateInstance(ctx, instanceID); err != nil {
-			if p.debug {
-				fmt.Printf("[kubeadm] warning: failed to terminate %s: %v\n", instanceID, err)
-			}
+	for _, instance := range instances {
+		kubeadmLog.Debugf("terminating instance %s", instance.ID)
+		if err := p.backend.Terminate(ctx, instance.ID); err != nil {
+			kubeadmLog.Debugf("warning: failed to terminate %s: %v", instance.ID, err)
 		}
 	}
 
 	// Wait for instances to terminate
 	time.Sleep(DefaultPollInterval)
 
-	// Delete security group
-	sgID, err := p.findSecurityGroup(ctx, clusterName)
-	if err == nil && sgID != "" {
-		if p.debug {
-			fmt.Printf("[kubeadm] deleting security group %s\n", sgID)
-		}
-		_ = p.deleteSecurityGroup(ctx, sgID)
+	// Delete the network and firewall
+	if err := p.backend.CleanupCluster(ctx, clusterName); err != nil {
+		kubeadmLog.Debugf("warning: cluster network cleanup: %v", err)
 	}
 
-	if p.debug {
-		fmt.Printf("[kubeadm] cluster %s deleted\n", clusterName)
+	// Forget any interrupted creation
+	if err := DeleteKubeadmCreateState(clusterName); err != nil {
+		kubeadmLog.Debugf("warning: failed to remove creation state: %v", err)
 	}
 
+	kubeadmLog.Debugf("cluster %s deleted", clusterName)
+
 	return nil
 }
 
@@ -374,9 +739,7 @@ func (p *KubeadmProvider) Scale(ctx context.Context, clusterName string, opts Sc
 	currentCount := len(cluster.WorkerNodes)
 	desiredCount := opts.DesiredCount
 
-	if p.debug {
-		fmt.Printf("[kubeadm] scaling cluster %s from %d to %d workers\n", clusterName, currentCount, desiredCount)
-	}
+	kubeadmLog.Debugf("scaling cluster %s from %d to %d workers", clusterName, currentCount, desiredCount)
 
 	if desiredCount > currentCount {
 		// Scale up: add new workers
@@ -404,23 +767,12 @@ func (p *KubeadmProvider) GetKubeconfig(ctx context.Context, clusterName string)
 		return "", fmt.Errorf("no control plane nodes found")
 	}
 
-	cpIP := cluster.ControlPlaneNodes[0].ExternalIP
-	if cpIP == "" {
-		cpIP = cluster.ControlPlaneNodes[0].InternalIP
-	}
+	cp := cluster.ControlPlaneNodes[0]
+	cpIP := p.sshAddress(cp.ExternalIP, cp.InternalIP)
 
 	// Connect to control plane and get kubeconfig
-	ssh, err := NewSSHClient(SSHClientOptions{
-		Host:           cpIP,
-		User:           "ubuntu",
-		PrivateKeyPath: p.sshKeyPath,
-		Debug:          p.debug,
-	})
+	ssh, err := p.dial(ctx, cpIP)
 	if err != nil {
-		return "", fmt.Errorf("failed to create SSH client: %w", err)
-	}
-
-	if err := ssh.Connect(ctx); err != nil {
 		return "", fmt.Errorf("failed to connect to control plane: %w", err)
 	}
 	defer ssh.Close()
@@ -466,25 +818,12 @@ func (p *KubeadmProvider) Health(ctx context.Context, clusterName string) (*Heal
 		return status, nil
 	}
 
-	cpIP := cluster.ControlPlaneNodes[0].ExternalIP
-	if cpIP == "" {
-		cpIP = cluster.ControlPlaneNodes[0].InternalIP
-	}
+	cp := cluster.ControlPlaneNodes[0]
+	cpIP := p.sshAddress(cp.ExternalIP, cp.InternalIP)
 
 	// Connect and check nodes
-	ssh, err := NewSSHClient(SSHClientOptions{
-		Host:           cpIP,
-		User:           "ubuntu",
-		PrivateKeyPath: p.sshKeyPath,
-		Debug:          p.debug,
-	})
+	ssh, err := p.dial(ctx, cpIP)
 	if err != nil {
-		status.Healthy = false
-		status.Message = fmt.Sprintf("failed to create SSH client: %v", err)
-		return status, nil
-	}
-
-	if err := ssh.Connect(ctx); err != nil {
 		status.Healthy = false
 		status.Message = fmt.Sprintf("failed to connect: %v", err)
 		return status, nil
@@ -543,38 +882,50 @@ func (p *KubeadmProvider) Health(ctx context.Context, clusterName string) (*Heal
 
 	status.Components["control-plane"] = "ACTIVE"
 
-	return status, nil
-}
-
-// ListClusters returns all kubeadm clusters in the region
-func (p *KubeadmProvider) ListClusters(ctx context.Context) ([]ClusterInfo, error) {
-	// Find all clusters by looking for security groups with our tag pattern
-	output, err := p.runAWS(ctx, "ec2", "describe-security-groups",
-		"--filters", "Name=tag-key,Values=kubernetes.io/cluster/*",
-		"--query", "SecurityGroups[*].Tags[?Key=='kubernetes.io/cluster'].Value",
-		"--output", "json")
+	// Stacked etcd runs on every control plane node; report its members so
+	// a lost member or quorum shows up before the API server fails
+	members, err := EtcdMembers(ctx, ssh)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list clusters: %w", err)
+		status.Components["etcd"] = fmt.Sprintf("unknown: %v", err)
+		return status, nil
 	}
 
-	var tagValues [][]string
-	if err := json.Unmarshal([]byte(output), &tagValues); err != nil {
-		return nil, fmt.Errorf("failed to parse cluster list: %w", err)
+	healthyMembers := 0
+	for _, member := range members {
+		memberStatus := "unhealthy"
+		if member.Healthy {
+			memberStatus = "healthy"
+			healthyMembers++
+		}
+		if member.Learner {
+			memberStatus += " (learner)"
+		}
+		status.Components["etcd/"+member.Name] = memberStatus
 	}
+	status.Components["etcd"] = fmt.Sprintf("%d/%d members healthy", healthyMembers, len(members))
 
-	// Extract unique cluster names
-	clusterNames := make(map[string]bool)
-	for _, tags := range tagValues {
-		for _, tag := range tags {
-			if tag != "" {
-				clusterNames[tag] = true
-			}
-		}
+	switch {
+	case healthyMembers < len(members)/2+1:
+		status.Healthy = false
+		status.Message += "; etcd has lost quorum"
+	case healthyMembers < len(members):
+		status.Healthy = false
+		status.Message += fmt.Sprintf("; %d etcd member(s) unhealthy", len(members)-healthyMembers)
+	}
+
+	return status, nil
+}
+
+// ListClusters returns all kubeadm clusters on the backend
+func (p *KubeadmProvider) ListClusters(ctx context.Context) ([]ClusterInfo, error) {
+	names, err := p.backend.ListClusters(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get details for each cluster
 	var clusters []ClusterInfo
-	for name := range clusterNames {
+	for _, name := range names {
 		cluster, err := p.GetCluster(ctx, name)
 		if err != nil {
 			continue
@@ -587,7 +938,7 @@ func (p *KubeadmProvider) ListClusters(ctx context.Context) ([]ClusterInfo, erro
 
 // GetCluster returns information about a specific cluster
 func (p *KubeadmProvider) GetCluster(ctx context.Context, clusterName string) (*ClusterInfo, error) {
-	instances, err := p.findClusterInstances(ctx, clusterName)
+	instances, err := p.backend.ListInstances(ctx, clusterName)
 	if err != nil {
 		return nil, err
 	}
@@ -596,309 +947,55 @@ func (p *KubeadmProvider) GetCluster(ctx context.Context, clusterName string) (*
 		return nil, &ErrClusterNotFound{ClusterName: clusterName}
 	}
 
-	// Get instance details - use filter instead of --instance-ids to avoid formatting issues
-	output, err := p.runAWS(ctx, "ec2", "describe-instances",
-		"--filters",
-		fmt.Sprintf("Name=instance-id,Values=%s", strings.Join(instances, ",")),
-		"--output", "json")
-	if err != nil {
-		return nil, fmt.Errorf("failed to describe instances: %w", err)
-	}
-
-	var result struct {
-		Reservations []struct {
-			Instances []struct {
-				InstanceID       string `json:"InstanceId"`
-				PrivateIPAddress string `json:"PrivateIpAddress"`
-				PublicIPAddress  string `json:"PublicIpAddress"`
-				State            struct {
-					Name string `json:"Name"`
-				} `json:"State"`
-				Tags []struct {
-					Key   string `json:"Key"`
-					Value string `json:"Value"`
-				} `json:"Tags"`
-			} `json:"Instances"`
-		} `json:"Reservations"`
-	}
-
-	if err := json.Unmarshal([]byte(output), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse instances: %w", err)
-	}
-
 	info := &ClusterInfo{
 		Name:   clusterName,
 		Type:   ClusterTypeKubeadm,
 		Status: "ACTIVE",
-		Region: p.region,
+		Region: p.backend.Region(),
 	}
 
-	for _, res := range result.Reservations {
-		for _, inst := range res.Instances {
-			if inst.State.Name != "running" {
-				continue
-			}
-
-			node := NodeInfo{
-				InternalIP: inst.PrivateIPAddress,
-				ExternalIP: inst.PublicIPAddress,
-				Status:     "Ready",
-			}
+	var loadBalancer string
+	for _, inst := range instances {
+		if !inst.Running {
+			continue
+		}
 
-			// Get role from tags
-			for _, tag := range inst.Tags {
-				if tag.Key == "kubernetes.io/role" {
-					node.Role = tag.Value
-				}
-				if tag.Key == "Name" {
-					node.Name = tag.Value
-				}
-			}
+		node := NodeInfo{
+			Name:       inst.Name,
+			Role:       inst.Role,
+			InternalIP: inst.PrivateIP,
+			ExternalIP: inst.PublicIP,
+			Status:     "Ready",
+		}
 
-			if node.Role == "control-plane" {
-				info.ControlPlaneNodes = append(info.ControlPlaneNodes, node)
-				info.Endpoint = fmt.Sprintf("https://%s:6443", node.ExternalIP)
-			} else {
-				info.WorkerNodes = append(info.WorkerNodes, node)
+		switch {
+		case node.Role == "lb":
+			// HA clusters are reached through the load balancer
+			loadBalancer = fmt.Sprintf("https://%s:6443", inst.nodeAddress())
+		case strings.HasPrefix(node.Role, "control-plane"):
+			node.Role = "control-plane"
+			info.ControlPlaneNodes = append(info.ControlPlaneNodes, node)
+			if inst.Role == "control-plane" {
+				info.Endpoint = fmt.Sprintf("https://%s:6443", inst.nodeAddress())
 			}
+		default:
+			info.WorkerNodes = append(info.WorkerNodes, node)
 		}
 	}
+	if loadBalancer != "" {
+		info.Endpoint = loadBalancer
+	}
 
 	return info, nil
 }
 
 // Internal helper methods
 
-type ec2Instance struct {
-	InstanceID string
-	PublicIP   string
-	PrivateIP  string
-}
-
-func (p *KubeadmProvider) launchInstance(ctx context.Context, clusterName, role, instanceType, sgID string, extraTags map[string]string) (*ec2Instance, error) {
-	// Get the latest Ubuntu 22.04 AMI
-	amiOutput, err := p.runAWS(ctx, "ec2", "describe-images",
-		"--owners", "099720109477",
-		"--filters",
-		"Name=name,Values=ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server-*",
-		"Name=state,Values=available",
-		"--query", "sort_by(Images, &CreationDate)[-1].ImageId",
-		"--output", "text")
-	if err != nil {
-		return nil, fmt.Errorf("failed to find Ubuntu AMI: %w", err)
-	}
-
-	amiID := strings.TrimSpace(amiOutput)
-
-	// Build tags
-	tags := []string{
-		fmt.Sprintf("Key=Name,Value=%s-%s", clusterName, role),
-		fmt.Sprintf("Key=kubernetes.io/cluster/%s,Value=owned", clusterName),
-		fmt.Sprintf("Key=kubernetes.io/role,Value=%s", role),
-	}
-	for k, v := range extraTags {
-		tags = append(tags, fmt.Sprintf("Key=%s,Value=%s", k, v))
-	}
-
-	tagSpec := fmt.Sprintf("ResourceType=instance,Tags=[{%s}]", strings.Join(tags, "},{"))
-
-	args := []string{
-		"ec2", "run-instances",
-		"--image-id", amiID,
-		"--instance-type", instanceType,
-		"--key-name", p.keyPairName,
-		"--security-group-ids", sgID,
-		"--tag-specifications", tagSpec,
-		"--associate-public-ip-address",
-		"--output", "json",
-	}
-
-	if p.subnetID != "" {
-		args = append(args, "--subnet-id", p.subnetID)
-	}
-
-	output, err := p.runAWS(ctx, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to launch instance: %w", err)
-	}
-
-	var result struct {
-		Instances []struct {
-			InstanceID       string `json:"InstanceId"`
-			PrivateIPAddress string `json:"PrivateIpAddress"`
-		} `json:"Instances"`
-	}
-
-	if err := json.Unmarshal([]byte(output), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse instance: %w", err)
-	}
-
-	if len(result.Instances) == 0 {
-		return nil, fmt.Errorf("no instance created")
-	}
-
-	instanceID := result.Instances[0].InstanceID
-
-	// Wait for instance to be running and get public IP
-	if p.debug {
-		fmt.Printf("[kubeadm] waiting for instance %s to be running...\n", instanceID)
-	}
-
-	_, err = p.runAWS(ctx, "ec2", "wait", "instance-running", "--instance-ids", instanceID)
-	if err != nil {
-		return nil, fmt.Errorf("instance did not start: %w", err)
-	}
-
-	// Get public IP
-	ipOutput, err := p.runAWS(ctx, "ec2", "describe-instances",
-		"--instance-ids", instanceID,
-		"--query", "Reservations[0].Instances[0].[PublicIpAddress,PrivateIpAddress]",
-		"--output", "text")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get instance IPs: %w", err)
-	}
-
-	ips := strings.Fields(ipOutput)
-	publicIP := ""
-	privateIP := result.Instances[0].PrivateIPAddress
-
-	if len(ips) >= 1 && ips[0] != "None" {
-		publicIP = ips[0]
-	}
-	if len(ips) >= 2 {
-		privateIP = ips[1]
-	}
-
-	return &ec2Instance{
-		InstanceID: instanceID,
-		PublicIP:   publicIP,
-		PrivateIP:  privateIP,
-	}, nil
-}
-
-func (p *KubeadmProvider) terminateInstance(ctx context.Context, instanceID string) error {
-	_, err := p.runAWS(ctx, "ec2", "terminate-instances", "--instance-ids", instanceID)
-	return err
-}
-
-func (p *KubeadmProvider) createSecurityGroup(ctx context.Context, clusterName string) (string, error) {
-	sgName := fmt.Sprintf("%s-k8s-sg", clusterName)
-
-	// Create security group
-	args := []string{
-		"ec2", "create-security-group",
-		"--group-name", sgName,
-		"--description", fmt.Sprintf("Security group for kubeadm cluster %s", clusterName),
-		"--output", "json",
-	}
-
-	if p.vpcID != "" {
-		args = append(args, "--vpc-id", p.vpcID)
-	}
-
-	output, err := p.runAWS(ctx, args...)
-	if err != nil {
-		return "", fmt.Errorf("failed to create security group: %w", err)
-	}
-
-	var result struct {
-		GroupID string `json:"GroupId"`
-	}
-	if err := json.Unmarshal([]byte(output), &result); err != nil {
-		return "", fmt.Errorf("failed to parse security group: %w", err)
-	}
-
-	sgID := result.GroupID
-
-	// Tag the security group
-	_, _ = p.runAWS(ctx, "ec2", "create-tags",
-		"--resources", sgID,
-		"--tags",
-		fmt.Sprintf("Key=Name,Value=%s", sgName),
-		fmt.Sprintf("Key=kubernetes.io/cluster/%s,Value=owned", clusterName))
-
-	// Add ingress rules
-	rules := []struct {
-		port     string
-		protocol string
-		desc     string
-	}{
-		{"22", "tcp", "SSH"},
-		{"6443", "tcp", "Kubernetes API"},
-		{"2379-2380", "tcp", "etcd"},
-		{"10250-10252", "tcp", "Kubelet"},
-		{"30000-32767", "tcp", "NodePort Services"},
-	}
-
-	for _, rule := range rules {
-		_, _ = p.runAWS(ctx, "ec2", "authorize-security-group-ingress",
-			"--group-id", sgID,
-			"--protocol", rule.protocol,
-			"--port", rule.port,
-			"--cidr", "0.0.0.0/0")
-	}
-
-	// Allow all traffic within the security group
-	_, _ = p.runAWS(ctx, "ec2", "authorize-security-group-ingress",
-		"--group-id", sgID,
-		"--protocol", "-1",
-		"--source-group", sgID)
-
-	return sgID, nil
-}
-
-func (p *KubeadmProvider) deleteSecurityGroup(ctx context.Context, sgID string) error {
-	_, err := p.runAWS(ctx, "ec2", "delete-security-group", "--group-id", sgID)
-	return err
-}
-
-func (p *KubeadmProvider) findSecurityGroup(ctx context.Context, clusterName string) (string, error) {
-	output, err := p.runAWS(ctx, "ec2", "describe-security-groups",
-		"--filters",
-		fmt.Sprintf("Name=tag:kubernetes.io/cluster/%s,Values=owned", clusterName),
-		"--query", "SecurityGroups[0].GroupId",
-		"--output", "text")
-	if err != nil {
-		return "", err
-	}
-
-	sgID := strings.TrimSpace(output)
-	if sgID == "" || sgID == "None" {
-		return "", fmt.Errorf("security group not found")
-	}
-
-	return sgID, nil
-}
-
-func (p *KubeadmProvider) findClusterInstances(ctx context.Context, clusterName string) ([]string, error) {
-	output, err := p.runAWS(ctx, "ec2", "describe-instances",
-		"--filters",
-		fmt.Sprintf("Name=tag:kubernetes.io/cluster/%s,Values=owned", clusterName),
-		"Name=instance-state-name,Values=running,pending",
-		"--query", "Reservations[*].Instances[*].InstanceId",
-		"--output", "json")
-	if err != nil {
-		return nil, err
-	}
-
-	var result [][]string
-	if err := json.Unmarshal([]byte(output), &result); err != nil {
-		return nil, err
-	}
-
-	var instances []string
-	for _, res := range result {
-		instances = append(instances, res...)
-	}
-
-	return instances, nil
-}
-
 func (p *KubeadmProvider) scaleUp(ctx context.Context, cluster *ClusterInfo, count int, opts ScaleOptions) error {
-	// Get security group
-	sgID, err := p.findSecurityGroup(ctx, cluster.Name)
+	// Get the cluster network
+	network, err := p.backend.FindCluster(ctx, cluster.Name)
 	if err != nil {
-		return fmt.Errorf("failed to find security group: %w", err)
+		return fmt.Errorf("failed to find cluster network: %w", err)
 	}
 
 	// Get join token from control plane
@@ -906,22 +1003,11 @@ func (p *KubeadmProvider) scaleUp(ctx context.Context, cluster *ClusterInfo, cou
 		return fmt.Errorf("no control plane nodes found")
 	}
 
-	cpIP := cluster.ControlPlaneNodes[0].ExternalIP
-	if cpIP == "" {
-		cpIP = cluster.ControlPlaneNodes[0].InternalIP
-	}
+	cp := cluster.ControlPlaneNodes[0]
+	cpIP := p.sshAddress(cp.ExternalIP, cp.InternalIP)
 
-	ssh, err := NewSSHClient(SSHClientOptions{
-		Host:           cpIP,
-		User:           "ubuntu",
-		PrivateKeyPath: p.sshKeyPath,
-		Debug:          p.debug,
-	})
+	ssh, err := p.dial(ctx, cpIP)
 	if err != nil {
-		return fmt.Errorf("failed to create SSH client: %w", err)
-	}
-
-	if err := ssh.Connect(ctx); err != nil {
 		return fmt.Errorf("failed to connect to control plane: %w", err)
 	}
 	defer ssh.Close()
@@ -933,54 +1019,25 @@ func (p *KubeadmProvider) scaleUp(ctx context.Context, cluster *ClusterInfo, cou
 
 	// Launch new workers
 	currentCount := len(cluster.WorkerNodes)
-	instanceType := "t3.medium"
+	instanceType := p.backend.DefaultInstanceType()
 
 	for i := 0; i < count; i++ {
 		workerIndex := currentCount + i
 
-		instance, err := p.launchInstance(ctx, cluster.Name, fmt.Sprintf("worker-%d", workerIndex), instanceType, sgID, nil)
-		if err != nil {
-			return fmt.Errorf("failed to launch worker %d: %w", workerIndex, err)
-		}
-
-		// Wait for SSH
-		if err := WaitForSSH(ctx, instance.PublicIP, 22, DefaultSSHConnectTimeout); err != nil {
-			continue
-		}
-
-		// Bootstrap and join
-		workerSSH, err := NewSSHClient(SSHClientOptions{
-			Host:           instance.PublicIP,
-			User:           "ubuntu",
-			PrivateKeyPath: p.sshKeyPath,
-			Debug:          p.debug,
+		instance, err := p.backend.Launch(ctx, LaunchRequest{
+			ClusterName:  cluster.Name,
+			Role:         fmt.Sprintf("worker-%d", workerIndex),
+			InstanceType: instanceType,
+			Network:      network,
 		})
 		if err != nil {
-			continue
-		}
-
-		if err := workerSSH.Connect(ctx); err != nil {
-			workerSSH.Close()
-			continue
-		}
-
-		bootstrapConfig := DefaultBootstrapConfig()
-		if err := BootstrapNode(ctx, workerSSH, bootstrapConfig); err != nil {
-			workerSSH.Close()
-			continue
+			return fmt.Errorf("failed to launch worker %d: %w", workerIndex, err)
 		}
 
-		joinConfig := bootstrapConfig
-		joinConfig.ControlPlaneIP = cluster.ControlPlaneNodes[0].InternalIP
-		joinConfig.JoinToken = joinOutput.Token
-		joinConfig.CACertHash = joinOutput.CACertHash
-
-		if err := JoinWorker(ctx, workerSSH, joinConfig); err != nil {
-			workerSSH.Close()
+		if err := p.joinNode(ctx, instance, DefaultBootstrapConfig(), joinOutput, cluster.ControlPlaneNodes[0].InternalIP); err != nil {
+			kubeadmLog.Debugf("warning: worker %d: %v", workerIndex, err)
 			continue
 		}
-
-		workerSSH.Close()
 	}
 
 	return nil
@@ -992,28 +1049,22 @@ func (p *KubeadmProvider) scaleDown(ctx context.Context, cluster *ClusterInfo, c
 		return fmt.Errorf("no control plane nodes found")
 	}
 
-	cpIP := cluster.ControlPlaneNodes[0].ExternalIP
-	if cpIP == "" {
-		cpIP = cluster.ControlPlaneNodes[0].InternalIP
-	}
+	cp := cluster.ControlPlaneNodes[0]
+	cpIP := p.sshAddress(cp.ExternalIP, cp.InternalIP)
 
-	ssh, err := NewSSHClient(SSHClientOptions{
-		Host:           cpIP,
-		User:           "ubuntu",
-		PrivateKeyPath: p.sshKeyPath,
-		Debug:          p.debug,
-	})
+	ssh, err := p.dial(ctx, cpIP)
 	if err != nil {
-		return fmt.Errorf("failed to create SSH client: %w", err)
-	}
-
-	if err := ssh.Connect(ctx); err != nil {
 		return fmt.Errorf("failed to connect to control plane: %w", err)
 	}
 	defer ssh.Close()
 
 	workersToRemove := cluster.WorkerNodes[len(cluster.WorkerNodes)-count:]
 
+	instances, err := p.backend.ListInstances(ctx, cluster.Name)
+	if err != nil {
+		return fmt.Errorf("failed to find cluster instances: %w", err)
+	}
+
 	for _, worker := range workersToRemove {
 		// Drain the node
 		_, _ = ssh.Run(ctx, fmt.Sprintf("kubectl drain %s --ignore-daemonsets --delete-emptydir-data --force", worker.Name))
@@ -1022,20 +1073,9 @@ func (p *KubeadmProvider) scaleDown(ctx context.Context, cluster *ClusterInfo, c
 		_, _ = ssh.Run(ctx, fmt.Sprintf("kubectl delete node %s", worker.Name))
 
 		// Find and terminate the instance
-		instances, err := p.findClusterInstances(ctx, cluster.Name)
-		if err != nil {
-			continue
-		}
-
-		for _, instanceID := range instances {
-			// Check if this is the worker we want to remove
-			output, _ := p.runAWS(ctx, "ec2", "describe-instances",
-				"--instance-ids", instanceID,
-				"--query", "Reservations[0].Instances[0].PrivateIpAddress",
-				"--output", "text")
-
-			if strings.TrimSpace(output) == worker.InternalIP {
-				_ = p.terminateInstance(ctx, instanceID)
+		for _, instance := range instances {
+			if instance.PrivateIP == worker.InternalIP {
+				_ = p.backend.Terminate(ctx, instance.ID)
 				break
 			}
 		}
@@ -1043,32 +1083,3 @@ func (p *KubeadmProvider) scaleDown(ctx context.Context, cluster *ClusterInfo, c
 
 	return nil
 }
-
-func (p *KubeadmProvider) runAWS(ctx context.Context, args ...string) (string, error) {
-	args = append(args, "--no-cli-pager")
-
-	if p.awsProfile != "" {
-		args = append(args, "--profile", p.awsProfile)
-	}
-	if p.region != "" {
-		args = append(args, "--region", p.region)
-	}
-
-	cmd := exec.CommandContext(ctx, "aws", args...)
-	cmd.Env = os.Environ()
-
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if p.debug {
-		fmt.Printf("[aws] %s\n", strings.Join(args, " "))
-	}
-
-	err := cmd.Run()
-	if err != nil {
-		return "", fmt.Errorf("aws command failed: %w, stderr: %s", err, stderr.String())
-	}
-
-	return stdout.String(), nil
-}
@@ -0,0 +1,67 @@
+package cluster
+
+import "context"
+
+// InstanceBackend provisions and tracks the machines a kubeadm cluster runs
+// on. KubeadmProvider drives kubeadm over SSH and leaves the cloud-specific
+// instance lifecycle (network, firewall, servers) to the backend.
+type InstanceBackend interface {
+	// Name identifies the backend ("ec2", "hetzner")
+	Name() string
+	// Region is the default region or location for new clusters
+	Region() string
+	// SSHUser is the login user of the backend's Ubuntu image
+	SSHUser() string
+	// DefaultInstanceType is used when no node type is given
+	DefaultInstanceType() string
+	// PrivateNetworkCIDR is the private network nodes talk over when it is
+	// not the default route (empty on EC2, where the VPC address is)
+	PrivateNetworkCIDR() string
+	// Validate checks the backend has what Create needs, such as an SSH key
+	Validate() error
+
+	// PrepareCluster creates the shared network and firewall for a new
+	// cluster and returns a handle passed to Launch
+	PrepareCluster(ctx context.Context, clusterName string) (string, error)
+	// FindCluster returns the handle PrepareCluster created
+	FindCluster(ctx context.Context, clusterName string) (string, error)
+	// CleanupCluster removes what PrepareCluster created. Instances must be
+	// terminated first.
+	CleanupCluster(ctx context.Context, clusterName string) error
+
+	// Launch starts an instance and waits until it has its addresses
+	Launch(ctx context.Context, req LaunchRequest) (*Instance, error)
+	// Terminate deletes an instance
+	Terminate(ctx context.Context, instanceID string) error
+	// ListInstances returns the cluster's pending and running instances
+	ListInstances(ctx context.Context, clusterName string) ([]Instance, error)
+	// ListClusters returns the names of clusters the backend can find
+	ListClusters(ctx context.Context) ([]string, error)
+}
+
+// LaunchRequest describes one kubeadm node to launch
+type LaunchRequest struct {
+	ClusterName  string
+	Role         string // "control-plane" or "worker-<n>"
+	InstanceType string
+	Network      string // handle from PrepareCluster
+	Tags         map[string]string
+}
+
+// Instance is a machine backing a kubeadm node
+type Instance struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+	PublicIP  string `json:"publicIp,omitempty"`
+	PrivateIP string `json:"privateIp,omitempty"`
+	Running   bool   `json:"running"`
+}
+
+// nodeAddress prefers the public address for SSH from outside the network
+func (i Instance) nodeAddress() string {
+	if i.PublicIP != "" {
+		return i.PublicIP
+	}
+	return i.PrivateIP
+}
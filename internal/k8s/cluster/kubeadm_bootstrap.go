@@ -21,6 +21,21 @@ type BootstrapConfig struct {
 	JoinToken         string
 	CACertHash        string
 	CNI               string // calico or flannel
+
+	// NodeIP pins the kubelet (and on the control plane, the API server
+	// advertise address) to a private network address. Empty uses the
+	// default route, which is the VPC address on EC2.
+	NodeIP string
+	// APIServerSANs replaces the EC2 metadata lookup for the API server
+	// certificate's extra SANs
+	APIServerSANs []string
+
+	// ControlPlaneEndpoint is the load-balanced host:port of an HA control
+	// plane. Nodes join through it instead of ControlPlaneIP.
+	ControlPlaneEndpoint string
+	// CertificateKey encrypts the control plane certificates kubeadm
+	// uploads for additional control plane nodes
+	CertificateKey string
 }
 
 // DefaultBootstrapConfig returns sensible defaults
@@ -60,6 +75,10 @@ func BootstrapNode(ctx context.Context, ssh *SSHClient, config BootstrapConfig)
 
 // InitializeControlPlane runs kubeadm init on the control plane
 func InitializeControlPlane(ctx context.Context, ssh *SSHClient, config BootstrapConfig) (*KubeadmInitOutput, error) {
+	if err := pinKubeletNodeIP(ctx, ssh, config.NodeIP); err != nil {
+		return nil, err
+	}
+
 	script := kubeadmInitScript(config)
 
 	output, err := ssh.RunSudoScript(ctx, script)
@@ -100,6 +119,10 @@ func InstallCNI(ctx context.Context, ssh *SSHClient, cni string) error {
 
 // JoinWorker runs kubeadm join on a worker node
 func JoinWorker(ctx context.Context, ssh *SSHClient, config BootstrapConfig) error {
+	if err := pinKubeletNodeIP(ctx, ssh, config.NodeIP); err != nil {
+		return err
+	}
+
 	script := kubeadmJoinScript(config)
 
 	if _, err := ssh.RunSudoScript(ctx, script); err != nil {
@@ -109,6 +132,26 @@ func JoinWorker(ctx context.Context, ssh *SSHClient, config BootstrapConfig) err
 	return nil
 }
 
+// PinCalicoNodeAddresses makes calico-node pick its address from the
+// private network instead of the first interface, which is public on
+// providers like Hetzner
+func PinCalicoNodeAddresses(ctx context.Context, ssh *SSHClient, cidr string) error {
+	if _, err := ssh.Run(ctx, fmt.Sprintf("kubectl -n kube-system set env daemonset/calico-node IP_AUTODETECTION_METHOD=cidr=%s", cidr)); err != nil {
+		return fmt.Errorf("failed to pin calico to %s: %w", cidr, err)
+	}
+	return nil
+}
+
+func pinKubeletNodeIP(ctx context.Context, ssh *SSHClient, nodeIP string) error {
+	if nodeIP == "" {
+		return nil
+	}
+	if _, err := ssh.RunSudoScript(ctx, kubeletNodeIPScript(nodeIP)); err != nil {
+		return fmt.Errorf("failed to set kubelet node IP: %w", err)
+	}
+	return nil
+}
+
 // KubeadmInitOutput contains parsed output from kubeadm init
 type KubeadmInitOutput struct {
 	JoinCommand string
@@ -237,6 +280,33 @@ systemctl enable kubelet
 }
 
 func kubeadmInitScript(config BootstrapConfig) string {
+	ha := ""
+	if config.ControlPlaneEndpoint != "" {
+		ha = fmt.Sprintf("  --control-plane-endpoint=%s \\\n", config.ControlPlaneEndpoint)
+	}
+	if config.CertificateKey != "" {
+		ha += fmt.Sprintf("  --certificate-key=%s \\\n", config.CertificateKey)
+	}
+
+	if len(config.APIServerSANs) > 0 {
+		advertise := ""
+		if config.NodeIP != "" {
+			advertise = fmt.Sprintf("  --apiserver-advertise-address=%s \\\n", config.NodeIP)
+		}
+		return fmt.Sprintf(`
+kubeadm init \
+  --pod-network-cidr=%s \
+  --service-cidr=%s \
+  --kubernetes-version=v%s.0 \
+%s%s  --apiserver-cert-extra-sans=%s \
+  --upload-certs
+
+# Print the join command for easy parsing
+echo "=== JOIN COMMAND ==="
+kubeadm token create --print-join-command
+`, config.PodCIDR, config.ServiceCIDR, config.KubernetesVersion, advertise, ha, strings.Join(config.APIServerSANs, ","))
+	}
+
 	// Get the public IP dynamically for TLS SAN
 	return fmt.Sprintf(`
 # Get public IP for TLS certificate SAN
@@ -247,13 +317,20 @@ kubeadm init \
   --pod-network-cidr=%s \
   --service-cidr=%s \
   --kubernetes-version=v%s.0 \
-  --apiserver-cert-extra-sans=${PUBLIC_IP},${PRIVATE_IP} \
+%s  --apiserver-cert-extra-sans=${PUBLIC_IP},${PRIVATE_IP} \
   --upload-certs
 
 # Print the join command for easy parsing
 echo "=== JOIN COMMAND ==="
 kubeadm token create --print-join-command
-`, config.PodCIDR, config.ServiceCIDR, config.KubernetesVersion)
+`, config.PodCIDR, config.ServiceCIDR, config.KubernetesVersion, ha)
+}
+
+func kubeletNodeIPScript(nodeIP string) string {
+	return fmt.Sprintf(`
+echo "KUBELET_EXTRA_ARGS=--node-ip=%s" > /etc/default/kubelet
+systemctl restart kubelet || true
+`, nodeIP)
 }
 
 func kubectlSetupScript() string {
@@ -265,11 +342,24 @@ sudo chown $(id -u):$(id -g) $HOME/.kube/config
 }
 
 func kubeadmJoinScript(config BootstrapConfig) string {
+	endpoint := config.ControlPlaneEndpoint
+	if endpoint == "" {
+		endpoint = config.ControlPlaneIP + ":6443"
+	}
+
+	controlPlane := ""
+	if config.IsControlPlane {
+		controlPlane = fmt.Sprintf(" \\\n  --control-plane \\\n  --certificate-key %s", config.CertificateKey)
+		if config.NodeIP != "" {
+			controlPlane += fmt.Sprintf(" \\\n  --apiserver-advertise-address %s", config.NodeIP)
+		}
+	}
+
 	return fmt.Sprintf(`
-kubeadm join %s:6443 \
+kubeadm join %s \
   --token %s \
-  --discovery-token-ca-cert-hash %s
-`, config.ControlPlaneIP, config.JoinToken, config.CACertHash)
+  --discovery-token-ca-cert-hash %s%s
+`, endpoint, config.JoinToken, config.CACertHash, controlPlane)
 }
 
 func calicoInstallScript() string {
@@ -0,0 +1,374 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/dryrun"
+)
+
+// ec2Backend runs kubeadm nodes on EC2 through the aws CLI
+type ec2Backend struct {
+	awsProfile  string
+	region      string
+	vpcID       string
+	subnetID    string
+	keyPairName string
+	debug       bool
+}
+
+func (b *ec2Backend) Name() string { return "ec2" }
+
+func (b *ec2Backend) Region() string { return b.region }
+
+func (b *ec2Backend) SSHUser() string { return "ubuntu" }
+
+func (b *ec2Backend) DefaultInstanceType() string { return "t3.medium" }
+
+func (b *ec2Backend) PrivateNetworkCIDR() string { return "" }
+
+func (b *ec2Backend) Validate() error {
+	if b.keyPairName == "" {
+		return &ErrInvalidConfiguration{Message: "SSH key pair name is required for kubeadm clusters"}
+	}
+	return nil
+}
+
+// PrepareCluster creates the cluster security group and returns its ID
+func (b *ec2Backend) PrepareCluster(ctx context.Context, clusterName string) (string, error) {
+	sgName := fmt.Sprintf("%s-k8s-sg", clusterName)
+
+	// Create security group
+	args := []string{
+		"ec2", "create-security-group",
+		"--group-name", sgName,
+		"--description", fmt.Sprintf("Security group for kubeadm cluster %s", clusterName),
+		"--output", "json",
+	}
+
+	if b.vpcID != "" {
+		args = append(args, "--vpc-id", b.vpcID)
+	}
+
+	output, err := b.runAWS(ctx, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create security group: %w", err)
+	}
+
+	var result struct {
+		GroupID string `json:"GroupId"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return "", fmt.Errorf("failed to parse security group: %w", err)
+	}
+
+	sgID := result.GroupID
+
+	// Tag the security group
+	_, _ = b.runAWS(ctx, "ec2", "create-tags",
+		"--resources", sgID,
+		"--tags",
+		fmt.Sprintf("Key=Name,Value=%s", sgName),
+		fmt.Sprintf("Key=kubernetes.io/cluster/%s,Value=owned", clusterName))
+
+	// Add ingress rules
+	rules := []struct {
+		port     string
+		protocol string
+		desc     string
+	}{
+		{"22", "tcp", "SSH"},
+		{"6443", "tcp", "Kubernetes API"},
+		{"2379-2380", "tcp", "etcd"},
+		{"10250-10252", "tcp", "Kubelet"},
+		{"30000-32767", "tcp", "NodePort Services"},
+	}
+
+	for _, rule := range rules {
+		_, _ = b.runAWS(ctx, "ec2", "authorize-security-group-ingress",
+			"--group-id", sgID,
+			"--protocol", rule.protocol,
+			"--port", rule.port,
+			"--cidr", "0.0.0.0/0")
+	}
+
+	// Allow all traffic within the security group
+	_, _ = b.runAWS(ctx, "ec2", "authorize-security-group-ingress",
+		"--group-id", sgID,
+		"--protocol", "-1",
+		"--source-group", sgID)
+
+	return sgID, nil
+}
+
+// FindCluster returns the cluster security group ID
+func (b *ec2Backend) FindCluster(ctx context.Context, clusterName string) (string, error) {
+	output, err := b.runAWS(ctx, "ec2", "describe-security-groups",
+		"--filters",
+		fmt.Sprintf("Name=tag:kubernetes.io/cluster/%s,Values=owned", clusterName),
+		"--query", "SecurityGroups[0].GroupId",
+		"--output", "text")
+	if err != nil {
+		return "", err
+	}
+
+	sgID := strings.TrimSpace(output)
+	if sgID == "" || sgID == "None" {
+		return "", fmt.Errorf("security group not found")
+	}
+
+	return sgID, nil
+}
+
+// CleanupCluster deletes the cluster security group
+func (b *ec2Backend) CleanupCluster(ctx context.Context, clusterName string) error {
+	sgID, err := b.FindCluster(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	kubeadmLog.Debugf("deleting security group %s", sgID)
+	_, err = b.runAWS(ctx, "ec2", "delete-security-group", "--group-id", sgID)
+	return err
+}
+
+func (b *ec2Backend) Launch(ctx context.Context, req LaunchRequest) (*Instance, error) {
+	// Get the latest Ubuntu 22.04 AMI
+	amiOutput, err := b.runAWS(ctx, "ec2", "describe-images",
+		"--owners", "099720109477",
+		"--filters",
+		"Name=name,Values=ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server-*",
+		"Name=state,Values=available",
+		"--query", "sort_by(Images, &CreationDate)[-1].ImageId",
+		"--output", "text")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Ubuntu AMI: %w", err)
+	}
+
+	amiID := strings.TrimSpace(amiOutput)
+
+	// Build tags
+	tags := []string{
+		fmt.Sprintf("Key=Name,Value=%s-%s", req.ClusterName, req.Role),
+		fmt.Sprintf("Key=kubernetes.io/cluster/%s,Value=owned", req.ClusterName),
+		fmt.Sprintf("Key=kubernetes.io/role,Value=%s", req.Role),
+	}
+	for k, v := range req.Tags {
+		tags = append(tags, fmt.Sprintf("Key=%s,Value=%s", k, v))
+	}
+
+	tagSpec := fmt.Sprintf("ResourceType=instance,Tags=[{%s}]", strings.Join(tags, "},{"))
+
+	args := []string{
+		"ec2", "run-instances",
+		"--image-id", amiID,
+		"--instance-type", req.InstanceType,
+		"--key-name", b.keyPairName,
+		"--security-group-ids", req.Network,
+		"--tag-specifications", tagSpec,
+		"--associate-public-ip-address",
+		"--output", "json",
+	}
+
+	if b.subnetID != "" {
+		args = append(args, "--subnet-id", b.subnetID)
+	}
+
+	output, err := b.runAWS(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch instance: %w", err)
+	}
+
+	var result struct {
+		Instances []struct {
+			InstanceID       string `json:"InstanceId"`
+			PrivateIPAddress string `json:"PrivateIpAddress"`
+		} `json:"Instances"`
+	}
+
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse instance: %w", err)
+	}
+
+	if len(result.Instances) == 0 {
+		return nil, fmt.Errorf("no instance created")
+	}
+
+	instanceID := result.Instances[0].InstanceID
+
+	// Wait for instance to be running and get public IP
+	kubeadmLog.Debugf("waiting for instance %s to be running...", instanceID)
+
+	_, err = b.runAWS(ctx, "ec2", "wait", "instance-running", "--instance-ids", instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("instance did not start: %w", err)
+	}
+
+	// Get public IP
+	ipOutput, err := b.runAWS(ctx, "ec2", "describe-instances",
+		"--instance-ids", instanceID,
+		"--query", "Reservations[0].Instances[0].[PublicIpAddress,PrivateIpAddress]",
+		"--output", "text")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance IPs: %w", err)
+	}
+
+	ips := strings.Fields(ipOutput)
+	publicIP := ""
+	privateIP := result.Instances[0].PrivateIPAddress
+
+	if len(ips) >= 1 && ips[0] != "None" {
+		publicIP = ips[0]
+	}
+	if len(ips) >= 2 {
+		privateIP = ips[1]
+	}
+
+	return &Instance{
+		ID:        instanceID,
+		Name:      fmt.Sprintf("%s-%s", req.ClusterName, req.Role),
+		Role:      req.Role,
+		PublicIP:  publicIP,
+		PrivateIP: privateIP,
+		Running:   true,
+	}, nil
+}
+
+func (b *ec2Backend) Terminate(ctx context.Context, instanceID string) error {
+	_, err := b.runAWS(ctx, "ec2", "terminate-instances", "--instance-ids", instanceID)
+	return err
+}
+
+func (b *ec2Backend) ListInstances(ctx context.Context, clusterName string) ([]Instance, error) {
+	output, err := b.runAWS(ctx, "ec2", "describe-instances",
+		"--filters",
+		fmt.Sprintf("Name=tag:kubernetes.io/cluster/%s,Values=owned", clusterName),
+		"Name=instance-state-name,Values=running,pending",
+		"--output", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instances: %w", err)
+	}
+	return parseEC2Instances(output)
+}
+
+func parseEC2Instances(output string) ([]Instance, error) {
+	var result struct {
+		Reservations []struct {
+			Instances []struct {
+				InstanceID       string `json:"InstanceId"`
+				PrivateIPAddress string `json:"PrivateIpAddress"`
+				PublicIPAddress  string `json:"PublicIpAddress"`
+				State            struct {
+					Name string `json:"Name"`
+				} `json:"State"`
+				Tags []struct {
+					Key   string `json:"Key"`
+					Value string `json:"Value"`
+				} `json:"Tags"`
+			} `json:"Instances"`
+		} `json:"Reservations"`
+	}
+
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse instances: %w", err)
+	}
+
+	var instances []Instance
+	for _, res := range result.Reservations {
+		for _, inst := range res.Instances {
+			instance := Instance{
+				ID:        inst.InstanceID,
+				PublicIP:  inst.PublicIPAddress,
+				PrivateIP: inst.PrivateIPAddress,
+				Running:   inst.State.Name == "running",
+			}
+			for _, tag := range inst.Tags {
+				switch tag.Key {
+				case "kubernetes.io/role":
+					instance.Role = tag.Value
+				case "Name":
+					instance.Name = tag.Value
+				}
+			}
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+// ListClusters finds clusters by their tagged security groups
+func (b *ec2Backend) ListClusters(ctx context.Context) ([]string, error) {
+	output, err := b.runAWS(ctx, kubeadmSecurityGroupArgs()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+	return parseEC2ClusterNames(output)
+}
+
+// kubeadmSecurityGroupArgs lists the tag keys of the <name>-k8s-sg security
+// groups PrepareCluster creates. The group name keeps EKS security groups,
+// which carry the same cluster tag, out of the list.
+func kubeadmSecurityGroupArgs() []string {
+	return []string{
+		"ec2", "describe-security-groups",
+		"--filters", "Name=group-name,Values=*-k8s-sg", "Name=tag-key,Values=" + clusterTagPrefix + "*",
+		"--query", "SecurityGroups[].Tags[].Key",
+		"--output", "json",
+	}
+}
+
+// parseEC2ClusterNames extracts cluster names from kubernetes.io/cluster/<name>
+// tag keys
+func parseEC2ClusterNames(output string) ([]string, error) {
+	var keys []string
+	if err := json.Unmarshal([]byte(output), &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster list: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, key := range keys {
+		name, ok := strings.CutPrefix(key, clusterTagPrefix)
+		if ok && name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (b *ec2Backend) runAWS(ctx context.Context, args ...string) (string, error) {
+	args = append(args, "--no-cli-pager")
+
+	if b.awsProfile != "" {
+		args = append(args, "--profile", b.awsProfile)
+	}
+	if b.region != "" {
+		args = append(args, "--region", b.region)
+	}
+	if dryrun.Skip(os.Stdout, "aws", args) {
+		return "", dryrun.ErrStopped
+	}
+
+	cmd := exec.CommandContext(ctx, "aws", args...)
+	cmd.Env = os.Environ()
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	kubeadmLog.Debugf("%s", strings.Join(args, " "))
+
+	err := cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("aws command failed: %w, stderr: %s", err, stderr.String())
+	}
+
+	return stdout.String(), nil
+}
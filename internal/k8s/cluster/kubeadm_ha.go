@@ -0,0 +1,179 @@
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GenerateCertificateKey returns a key for kubeadm --certificate-key, the
+// same 32 random bytes `kubeadm certs certificate-key` prints
+func GenerateCertificateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate certificate key: %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// UploadCertificates re-uploads the control plane certificates under key.
+// kubeadm deletes uploaded certificates after two hours, so joins after
+// that need a fresh upload.
+func UploadCertificates(ctx context.Context, ssh *SSHClient, key string) error {
+	if _, err := ssh.RunSudo(ctx, fmt.Sprintf("kubeadm init phase upload-certs --upload-certs --certificate-key %s", key)); err != nil {
+		return fmt.Errorf("failed to upload control plane certificates: %w", err)
+	}
+	return nil
+}
+
+// JoinControlPlane runs kubeadm join --control-plane on an additional
+// control plane node
+func JoinControlPlane(ctx context.Context, ssh *SSHClient, config BootstrapConfig) error {
+	if config.CertificateKey == "" {
+		return fmt.Errorf("certificate key is required to join a control plane node")
+	}
+	config.IsControlPlane = true
+
+	if err := pinKubeletNodeIP(ctx, ssh, config.NodeIP); err != nil {
+		return err
+	}
+
+	if _, err := ssh.RunSudoScript(ctx, kubeadmJoinScript(config)); err != nil {
+		return fmt.Errorf("kubeadm join --control-plane failed: %w", err)
+	}
+
+	// Setup kubectl for the user
+	if _, err := ssh.RunScript(ctx, kubectlSetupScript()); err != nil {
+		return fmt.Errorf("failed to setup kubectl: %w", err)
+	}
+
+	return nil
+}
+
+// ConfigureLoadBalancer installs HAProxy on the load balancer node and
+// points it at the API servers. Running it again replaces the backends.
+func ConfigureLoadBalancer(ctx context.Context, ssh *SSHClient, apiServers []string) error {
+	if _, err := ssh.RunSudoScript(ctx, haproxyScript(apiServers)); err != nil {
+		return fmt.Errorf("failed to configure HAProxy: %w", err)
+	}
+	return nil
+}
+
+// EtcdMember is one member of the control plane's stacked etcd cluster
+type EtcdMember struct {
+	ID        string
+	Name      string
+	ClientURL string
+	Healthy   bool
+	Learner   bool
+}
+
+// etcdctlCommand runs etcdctl inside the first etcd static pod
+const etcdctlCommand = `ETCD_POD=$(kubectl -n kube-system get pods -l component=etcd -o jsonpath='{.items[0].metadata.name}') && ` +
+	`kubectl -n kube-system exec "$ETCD_POD" -- etcdctl ` +
+	`--endpoints=https://127.0.0.1:2379 ` +
+	`--cacert=/etc/kubernetes/pki/etcd/ca.crt ` +
+	`--cert=/etc/kubernetes/pki/etcd/server.crt ` +
+	`--key=/etc/kubernetes/pki/etcd/server.key `
+
+// EtcdMembers lists the etcd members with their health, as seen from the
+// control plane node ssh is connected to
+func EtcdMembers(ctx context.Context, ssh *SSHClient) ([]EtcdMember, error) {
+	memberOutput, err := ssh.Run(ctx, etcdctlCommand+"member list -w json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list etcd members: %w", err)
+	}
+
+	// Unhealthy endpoints make etcdctl exit non-zero but still print JSON
+	healthOutput, _ := ssh.Run(ctx, etcdctlCommand+"endpoint health --cluster -w json")
+
+	return parseEtcdMembers(memberOutput, healthOutput)
+}
+
+func parseEtcdMembers(memberOutput, healthOutput string) ([]EtcdMember, error) {
+	var list struct {
+		Members []struct {
+			ID         uint64   `json:"ID"`
+			Name       string   `json:"name"`
+			ClientURLs []string `json:"clientURLs"`
+			IsLearner  bool     `json:"isLearner"`
+		} `json:"members"`
+	}
+	if err := json.Unmarshal([]byte(memberOutput), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse etcd members: %w", err)
+	}
+
+	var health []struct {
+		Endpoint string `json:"endpoint"`
+		Health   bool   `json:"health"`
+	}
+	healthy := make(map[string]bool)
+	if err := json.Unmarshal([]byte(strings.TrimSpace(healthOutput)), &health); err == nil {
+		for _, h := range health {
+			healthy[h.Endpoint] = h.Health
+		}
+	}
+
+	members := make([]EtcdMember, 0, len(list.Members))
+	for _, m := range list.Members {
+		member := EtcdMember{
+			ID:      fmt.Sprintf("%x", m.ID),
+			Name:    m.Name,
+			Learner: m.IsLearner,
+		}
+		for _, url := range m.ClientURLs {
+			if member.ClientURL == "" {
+				member.ClientURL = url
+			}
+			if healthy[url] {
+				member.Healthy = true
+			}
+		}
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+	return members, nil
+}
+
+func haproxyScript(apiServers []string) string {
+	var servers strings.Builder
+	for i, addr := range apiServers {
+		fmt.Fprintf(&servers, "    server control-plane-%d %s:6443 check\n", i, addr)
+	}
+
+	return fmt.Sprintf(`
+if ! command -v haproxy >/dev/null 2>&1; then
+  apt-get update
+  apt-get install -y haproxy
+fi
+
+cat <<EOF | tee /etc/haproxy/haproxy.cfg
+global
+    log /dev/log local0
+    daemon
+
+defaults
+    log global
+    mode tcp
+    option tcplog
+    timeout connect 5s
+    timeout client 1h
+    timeout server 1h
+
+frontend kube-apiserver
+    bind *:6443
+    default_backend control-plane
+
+backend control-plane
+    option tcp-check
+    balance roundrobin
+%sEOF
+
+systemctl enable haproxy
+systemctl restart haproxy
+`, servers.String())
+}
@@ -0,0 +1,156 @@
+package cluster
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// fakeBackend serves a fixed set of instances
+type fakeBackend struct {
+	ec2Backend
+	instances []Instance
+}
+
+func (b *fakeBackend) ListInstances(ctx context.Context, clusterName string) ([]Instance, error) {
+	return b.instances, nil
+}
+
+func TestGetClusterHA(t *testing.T) {
+	backend := &fakeBackend{instances: []Instance{
+		{ID: "i-1", Name: "demo-control-plane", Role: "control-plane", PublicIP: "203.0.113.10", PrivateIP: "10.0.1.10", Running: true},
+		{ID: "i-2", Name: "demo-control-plane-1", Role: "control-plane-1", PrivateIP: "10.0.1.11", Running: true},
+		{ID: "i-3", Name: "demo-control-plane-2", Role: "control-plane-2", PrivateIP: "10.0.1.12", Running: true},
+		{ID: "i-4", Name: "demo-lb", Role: "lb", PublicIP: "203.0.113.5", PrivateIP: "10.0.1.5", Running: true},
+		{ID: "i-5", Name: "demo-worker-0", Role: "worker-0", PrivateIP: "10.0.1.20", Running: true},
+	}}
+	provider := NewKubeadmProvider(KubeadmProviderOptions{Backend: backend})
+
+	info, err := provider.GetCluster(context.Background(), "demo")
+	if err != nil {
+		t.Fatalf("GetCluster: %v", err)
+	}
+	if len(info.ControlPlaneNodes) != 3 || len(info.WorkerNodes) != 1 {
+		t.Errorf("control planes = %d, workers = %d; want 3, 1", len(info.ControlPlaneNodes), len(info.WorkerNodes))
+	}
+	if info.Endpoint != "https://203.0.113.5:6443" {
+		t.Errorf("Endpoint = %s, want the load balancer", info.Endpoint)
+	}
+	for _, node := range info.ControlPlaneNodes {
+		if node.Role != "control-plane" {
+			t.Errorf("control plane node %s has role %s", node.Name, node.Role)
+		}
+	}
+}
+
+func TestCreateRejectsEvenControlPlaneCount(t *testing.T) {
+	provider := NewKubeadmProvider(KubeadmProviderOptions{Backend: &fakeBackend{}})
+
+	_, err := provider.Create(context.Background(), CreateOptions{Name: "demo", Region: "us-east-1", ControlPlaneCount: 2})
+	if err == nil || !strings.Contains(err.Error(), "must be odd") {
+		t.Errorf("Create with 2 control planes = %v, want odd count error", err)
+	}
+}
+
+func TestGenerateCertificateKey(t *testing.T) {
+	key, err := GenerateCertificateKey()
+	if err != nil {
+		t.Fatalf("GenerateCertificateKey: %v", err)
+	}
+	if raw, err := hex.DecodeString(key); err != nil || len(raw) != 32 {
+		t.Errorf("key %q is not 32 hex-encoded bytes", key)
+	}
+}
+
+func TestKubeadmInitScriptHA(t *testing.T) {
+	config := DefaultBootstrapConfig()
+	config.ControlPlaneEndpoint = "203.0.113.5:6443"
+	config.CertificateKey = "abc123"
+	script := kubeadmInitScript(config)
+
+	for _, want := range []string{"--control-plane-endpoint=203.0.113.5:6443", "--certificate-key=abc123", "--upload-certs"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %s:\n%s", want, script)
+		}
+	}
+}
+
+func TestKubeadmJoinScriptControlPlane(t *testing.T) {
+	config := BootstrapConfig{
+		ControlPlaneIP:       "10.0.1.10",
+		ControlPlaneEndpoint: "203.0.113.5:6443",
+		JoinToken:            "abc123.xyz789",
+		CACertHash:           "sha256:abcdef",
+		IsControlPlane:       true,
+		CertificateKey:       "key123",
+		NodeIP:               "10.0.1.11",
+	}
+	script := kubeadmJoinScript(config)
+
+	for _, want := range []string{
+		"kubeadm join 203.0.113.5:6443",
+		"--control-plane",
+		"--certificate-key key123",
+		"--apiserver-advertise-address 10.0.1.11",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %s:\n%s", want, script)
+		}
+	}
+
+	// Workers join through the endpoint without control plane flags
+	config.IsControlPlane = false
+	script = kubeadmJoinScript(config)
+	if !strings.Contains(script, "kubeadm join 203.0.113.5:6443") || strings.Contains(script, "--control-plane") {
+		t.Errorf("unexpected worker join script:\n%s", script)
+	}
+}
+
+func TestHAProxyScript(t *testing.T) {
+	script := haproxyScript([]string{"10.0.1.10", "10.0.1.11"})
+
+	for _, want := range []string{
+		"bind *:6443",
+		"server control-plane-0 10.0.1.10:6443 check",
+		"server control-plane-1 10.0.1.11:6443 check",
+		"systemctl restart haproxy",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %s:\n%s", want, script)
+		}
+	}
+}
+
+func TestParseEtcdMembers(t *testing.T) {
+	memberOutput := `{"header":{"cluster_id":1},"members":[
+		{"ID":12345678901234567890,"name":"demo-control-plane-1","peerURLs":["https://10.0.1.11:2380"],"clientURLs":["https://10.0.1.11:2379"]},
+		{"ID":1311768467463790320,"name":"demo-control-plane","peerURLs":["https://10.0.1.10:2380"],"clientURLs":["https://10.0.1.10:2379"]},
+		{"ID":42,"name":"demo-control-plane-2","clientURLs":["https://10.0.1.12:2379"],"isLearner":true}
+	]}`
+	healthOutput := `[
+		{"endpoint":"https://10.0.1.10:2379","health":true,"took":"10ms"},
+		{"endpoint":"https://10.0.1.11:2379","health":false,"error":"context deadline exceeded"}
+	]`
+
+	members, err := parseEtcdMembers(memberOutput, healthOutput)
+	if err != nil {
+		t.Fatalf("parseEtcdMembers: %v", err)
+	}
+	if len(members) != 3 {
+		t.Fatalf("got %d members, want 3", len(members))
+	}
+	if members[0].Name != "demo-control-plane" || !members[0].Healthy || members[0].ID != "123456789abcdef0" {
+		t.Errorf("members[0] = %+v", members[0])
+	}
+	if members[1].Healthy {
+		t.Errorf("members[1] should be unhealthy: %+v", members[1])
+	}
+	if !members[2].Learner || members[2].Healthy {
+		t.Errorf("members[2] = %+v, want unhealthy learner", members[2])
+	}
+
+	if _, err := parseEtcdMembers("not json", ""); err == nil {
+		t.Error("expected error for invalid member list")
+	}
+}
@@ -0,0 +1,330 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/dryrun"
+)
+
+// Hetzner labels that mark kubeadm cluster servers
+const (
+	hetznerClusterLabel = "clanker.io/cluster"
+	hetznerRoleLabel    = "clanker.io/role"
+)
+
+// hetznerPrivateCIDR is the private network nodes and the API server use;
+// Hetzner firewalls only filter public interfaces
+const hetznerPrivateCIDR = "10.0.0.0/16"
+
+// hetznerLabelValuePattern is what Hetzner accepts as a label value
+var hetznerLabelValuePattern = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9._-]{0,61}[a-zA-Z0-9])?)?$`)
+
+// HcloudRunner runs hcloud CLI commands; *hetzner.Client satisfies it
+type HcloudRunner interface {
+	RunHcloud(ctx context.Context, args ...string) (string, error)
+}
+
+// HetznerBackendOptions configures the Hetzner Cloud kubeadm backend
+type HetznerBackendOptions struct {
+	Client     HcloudRunner
+	Location   string // fsn1 when empty
+	SSHKeyName string // name of an SSH key uploaded to the Hetzner project
+	Image      string // ubuntu-22.04 when empty
+	Debug      bool
+}
+
+// hetznerBackend runs kubeadm nodes on Hetzner Cloud servers. Each cluster
+// gets a private network and a firewall, both named <cluster>-k8s.
+type hetznerBackend struct {
+	client     HcloudRunner
+	location   string
+	sshKeyName string
+	image      string
+	debug      bool
+}
+
+// NewHetznerBackend creates a Hetzner Cloud backend for KubeadmProvider
+func NewHetznerBackend(opts HetznerBackendOptions) InstanceBackend {
+	location := opts.Location
+	if location == "" {
+		location = "fsn1"
+	}
+	image := opts.Image
+	if image == "" {
+		image = "ubuntu-22.04"
+	}
+	return &hetznerBackend{
+		client:     opts.Client,
+		location:   location,
+		sshKeyName: opts.SSHKeyName,
+		image:      image,
+		debug:      opts.Debug,
+	}
+}
+
+func (b *hetznerBackend) Name() string { return "hetzner" }
+
+// hcloud runs an hcloud command. On a dry run a command that changes
+// resources is printed instead, and stops the run.
+func (b *hetznerBackend) hcloud(ctx context.Context, args ...string) (string, error) {
+	if dryrun.Skip(os.Stdout, "hcloud", args) {
+		return "", dryrun.ErrStopped
+	}
+	return b.client.RunHcloud(ctx, args...)
+}
+
+func (b *hetznerBackend) Region() string { return b.location }
+
+func (b *hetznerBackend) SSHUser() string { return "root" }
+
+func (b *hetznerBackend) DefaultInstanceType() string { return "cx22" }
+
+func (b *hetznerBackend) PrivateNetworkCIDR() string { return hetznerPrivateCIDR }
+
+func (b *hetznerBackend) Validate() error {
+	if b.client == nil {
+		return &ErrInvalidConfiguration{Message: "hetzner api_token is required (set hetzner.api_token or HCLOUD_TOKEN)"}
+	}
+	if b.sshKeyName == "" {
+		return &ErrInvalidConfiguration{Message: "a Hetzner SSH key name is required for kubeadm clusters"}
+	}
+	return nil
+}
+
+func hetznerResourceName(clusterName string) string {
+	return clusterName + "-k8s"
+}
+
+// PrepareCluster creates the private network and the firewall. Only SSH,
+// the API server and NodePorts are open publicly; node-to-node traffic
+// stays on the private network.
+func (b *hetznerBackend) PrepareCluster(ctx context.Context, clusterName string) (string, error) {
+	name := hetznerResourceName(clusterName)
+	label := hetznerClusterLabel + "=" + clusterName
+
+	if _, err := b.hcloud(ctx, "network", "create",
+		"--name", name, "--ip-range", hetznerPrivateCIDR, "--label", label); err != nil {
+		return "", fmt.Errorf("failed to create network: %w", err)
+	}
+	if _, err := b.hcloud(ctx, "network", "add-subnet", name,
+		"--network-zone", hetznerNetworkZone(b.location), "--type", "cloud", "--ip-range", "10.0.1.0/24"); err != nil {
+		_ = b.CleanupCluster(ctx, clusterName)
+		return "", fmt.Errorf("failed to create subnet: %w", err)
+	}
+
+	if _, err := b.hcloud(ctx, "firewall", "create", "--name", name, "--label", label); err != nil {
+		_ = b.CleanupCluster(ctx, clusterName)
+		return "", fmt.Errorf("failed to create firewall: %w", err)
+	}
+	rules := []struct {
+		port string
+		desc string
+	}{
+		{"22", "SSH"},
+		{"6443", "Kubernetes API"},
+		{"30000-32767", "NodePort Services"},
+	}
+	for _, rule := range rules {
+		if _, err := b.hcloud(ctx, "firewall", "add-rule", name,
+			"--direction", "in", "--protocol", "tcp", "--port", rule.port,
+			"--source-ips", "0.0.0.0/0", "--source-ips", "::/0",
+			"--description", rule.desc); err != nil {
+			_ = b.CleanupCluster(ctx, clusterName)
+			return "", fmt.Errorf("failed to add firewall rule for %s: %w", rule.desc, err)
+		}
+	}
+
+	return name, nil
+}
+
+func (b *hetznerBackend) FindCluster(ctx context.Context, clusterName string) (string, error) {
+	name := hetznerResourceName(clusterName)
+	if _, err := b.hcloud(ctx, "network", "describe", name, "-o", "json"); err != nil {
+		return "", fmt.Errorf("network %s not found: %w", name, err)
+	}
+	return name, nil
+}
+
+// CleanupCluster deletes the firewall and the network
+func (b *hetznerBackend) CleanupCluster(ctx context.Context, clusterName string) error {
+	name := hetznerResourceName(clusterName)
+	var errs []error
+	for _, resource := range []string{"firewall", "network"} {
+		kubeadmLog.Debugf("deleting %s %s", resource, name)
+		if _, err := b.hcloud(ctx, resource, "delete", name); err != nil && !isHcloudNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete %s %s: %w", resource, name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *hetznerBackend) Launch(ctx context.Context, req LaunchRequest) (*Instance, error) {
+	name := fmt.Sprintf("%s-%s", req.ClusterName, req.Role)
+	args := []string{
+		"server", "create",
+		"--name", name,
+		"--type", req.InstanceType,
+		"--image", b.image,
+		"--location", b.location,
+		"--ssh-key", b.sshKeyName,
+		"--firewall", req.Network,
+		"--network", req.Network,
+		"--label", hetznerClusterLabel + "=" + req.ClusterName,
+		"--label", hetznerRoleLabel + "=" + req.Role,
+	}
+	keys := make([]string, 0, len(req.Tags))
+	for k := range req.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if hetznerLabelValuePattern.MatchString(req.Tags[k]) {
+			args = append(args, "--label", k+"="+req.Tags[k])
+		} else {
+			kubeadmLog.Debugf("skipping tag %s: not a valid Hetzner label value", k)
+		}
+	}
+
+	kubeadmLog.Debugf("creating server %s...", name)
+	if _, err := b.hcloud(ctx, args...); err != nil {
+		return nil, fmt.Errorf("failed to create server: %w", err)
+	}
+
+	// server create waits for the server to boot; describe returns the
+	// private address once the network is attached
+	output, err := b.hcloud(ctx, "server", "describe", name, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe server %s: %w", name, err)
+	}
+	var server hcloudServer
+	if err := json.Unmarshal([]byte(output), &server); err != nil {
+		return nil, fmt.Errorf("failed to parse server %s: %w", name, err)
+	}
+	instance := server.instance()
+	if instance.PrivateIP == "" {
+		return nil, fmt.Errorf("server %s has no address on network %s", name, req.Network)
+	}
+	return &instance, nil
+}
+
+func (b *hetznerBackend) Terminate(ctx context.Context, instanceID string) error {
+	_, err := b.hcloud(ctx, "server", "delete", instanceID)
+	return err
+}
+
+func (b *hetznerBackend) ListInstances(ctx context.Context, clusterName string) ([]Instance, error) {
+	servers, err := b.listServers(ctx, hetznerClusterLabel+"="+clusterName)
+	if err != nil {
+		return nil, err
+	}
+	instances := make([]Instance, 0, len(servers))
+	for _, server := range servers {
+		instances = append(instances, server.instance())
+	}
+	return instances, nil
+}
+
+// ListClusters finds clusters by the cluster label on their servers and
+// networks; a network without servers is left by a failed creation
+func (b *hetznerBackend) ListClusters(ctx context.Context) ([]string, error) {
+	servers, err := b.listServers(ctx, hetznerClusterLabel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+	output, err := b.hcloud(ctx, "network", "list", "--selector", hetznerClusterLabel, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+	var networks []struct {
+		Labels map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal([]byte(output), &networks); err != nil {
+		return nil, fmt.Errorf("failed to parse networks: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	add := func(labels map[string]string) {
+		if name := labels[hetznerClusterLabel]; name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, server := range servers {
+		add(server.Labels)
+	}
+	for _, network := range networks {
+		add(network.Labels)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (b *hetznerBackend) listServers(ctx context.Context, selector string) ([]hcloudServer, error) {
+	output, err := b.hcloud(ctx, "server", "list", "--selector", selector, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list servers: %w", err)
+	}
+	var servers []hcloudServer
+	if err := json.Unmarshal([]byte(output), &servers); err != nil {
+		return nil, fmt.Errorf("failed to parse servers: %w", err)
+	}
+	return servers, nil
+}
+
+// hcloudServer is the subset of `hcloud server describe -o json` used here
+type hcloudServer struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	PublicNet struct {
+		IPv4 struct {
+			IP string `json:"ip"`
+		} `json:"ipv4"`
+	} `json:"public_net"`
+	PrivateNet []struct {
+		IP string `json:"ip"`
+	} `json:"private_net"`
+	Labels map[string]string `json:"labels"`
+}
+
+func (s hcloudServer) instance() Instance {
+	instance := Instance{
+		ID:       strconv.FormatInt(s.ID, 10),
+		Name:     s.Name,
+		Role:     s.Labels[hetznerRoleLabel],
+		PublicIP: s.PublicNet.IPv4.IP,
+		// Servers still starting count as pending, like EC2
+		Running: s.Status == "running",
+	}
+	if len(s.PrivateNet) > 0 {
+		instance.PrivateIP = s.PrivateNet[0].IP
+	}
+	return instance
+}
+
+// hetznerNetworkZone maps a location to the network zone its subnets live in
+func hetznerNetworkZone(location string) string {
+	switch {
+	case strings.HasPrefix(location, "ash"):
+		return "us-east"
+	case strings.HasPrefix(location, "hil"):
+		return "us-west"
+	case strings.HasPrefix(location, "sin"):
+		return "ap-southeast"
+	default:
+		return "eu-central" // fsn1, nbg1, hel1
+	}
+}
+
+func isHcloudNotFound(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "not found")
+}
@@ -0,0 +1,179 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeHcloud records hcloud invocations and answers from canned output keyed
+// on the first two arguments ("server list", "server describe", ...)
+type fakeHcloud struct {
+	calls   [][]string
+	outputs map[string]string
+	errs    map[string]error
+}
+
+func (f *fakeHcloud) RunHcloud(ctx context.Context, args ...string) (string, error) {
+	f.calls = append(f.calls, args)
+	key := strings.Join(args[:2], " ")
+	if err, ok := f.errs[key]; ok {
+		return "", err
+	}
+	return f.outputs[key], nil
+}
+
+const hcloudServersJSON = `[
+	{"id":101,"name":"demo-control-plane","status":"running",
+	 "public_net":{"ipv4":{"ip":"203.0.113.10"}},
+	 "private_net":[{"ip":"10.0.1.2"}],
+	 "labels":{"clanker.io/cluster":"demo","clanker.io/role":"control-plane"}},
+	{"id":102,"name":"demo-worker-0","status":"starting",
+	 "public_net":{"ipv4":{"ip":"203.0.113.11"}},
+	 "private_net":[],
+	 "labels":{"clanker.io/cluster":"demo","clanker.io/role":"worker-0"}},
+	{"id":201,"name":"lab-control-plane","status":"running",
+	 "public_net":{"ipv4":{"ip":"203.0.113.20"}},
+	 "private_net":[{"ip":"10.0.1.2"}],
+	 "labels":{"clanker.io/cluster":"lab","clanker.io/role":"control-plane"}}
+]`
+
+func TestHetznerBackendDefaults(t *testing.T) {
+	backend := NewHetznerBackend(HetznerBackendOptions{})
+
+	if backend.Region() != "fsn1" {
+		t.Errorf("Region() = %s, want fsn1", backend.Region())
+	}
+	if backend.SSHUser() != "root" {
+		t.Errorf("SSHUser() = %s, want root", backend.SSHUser())
+	}
+	if backend.PrivateNetworkCIDR() == "" {
+		t.Error("PrivateNetworkCIDR() is empty")
+	}
+	if err := backend.Validate(); err == nil {
+		t.Error("Validate() should fail without a client")
+	}
+
+	backend = NewHetznerBackend(HetznerBackendOptions{Client: &fakeHcloud{}})
+	if err := backend.Validate(); err == nil {
+		t.Error("Validate() should fail without an SSH key name")
+	}
+}
+
+func TestHetznerBackendLaunch(t *testing.T) {
+	fake := &fakeHcloud{outputs: map[string]string{
+		"server describe": `{"id":101,"name":"demo-control-plane","status":"running",
+			"public_net":{"ipv4":{"ip":"203.0.113.10"}},"private_net":[{"ip":"10.0.1.2"}],
+			"labels":{"clanker.io/role":"control-plane"}}`,
+	}}
+	backend := NewHetznerBackend(HetznerBackendOptions{Client: fake, Location: "nbg1", SSHKeyName: "me"})
+
+	inst, err := backend.Launch(context.Background(), LaunchRequest{
+		ClusterName:  "demo",
+		Role:         "control-plane",
+		InstanceType: "cx32",
+		Network:      "demo-k8s",
+		Tags:         map[string]string{"team": "platform", "owner": "alice@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	if inst.ID != "101" || inst.PublicIP != "203.0.113.10" || inst.PrivateIP != "10.0.1.2" || inst.Role != "control-plane" {
+		t.Errorf("instance = %+v", inst)
+	}
+
+	create := strings.Join(fake.calls[0], " ")
+	for _, want := range []string{
+		"server create --name demo-control-plane --type cx32",
+		"--location nbg1 --ssh-key me --firewall demo-k8s --network demo-k8s",
+		"--label clanker.io/cluster=demo --label clanker.io/role=control-plane",
+		"--label team=platform",
+	} {
+		if !strings.Contains(create, want) {
+			t.Errorf("create args missing %q: %s", want, create)
+		}
+	}
+	// "@" is not a valid label value
+	if strings.Contains(create, "owner=") {
+		t.Errorf("invalid label value passed through: %s", create)
+	}
+}
+
+func TestHetznerBackendLaunchWithoutPrivateIP(t *testing.T) {
+	fake := &fakeHcloud{outputs: map[string]string{
+		"server describe": `{"id":101,"name":"demo-worker-0","status":"running","private_net":[]}`,
+	}}
+	backend := NewHetznerBackend(HetznerBackendOptions{Client: fake, SSHKeyName: "me"})
+
+	if _, err := backend.Launch(context.Background(), LaunchRequest{ClusterName: "demo", Role: "worker-0", Network: "demo-k8s"}); err == nil {
+		t.Error("Launch should fail when the server has no private address")
+	}
+}
+
+func TestHetznerBackendListInstances(t *testing.T) {
+	fake := &fakeHcloud{outputs: map[string]string{"server list": hcloudServersJSON}}
+	backend := NewHetznerBackend(HetznerBackendOptions{Client: fake})
+
+	instances, err := backend.ListInstances(context.Background(), "demo")
+	if err != nil {
+		t.Fatalf("ListInstances: %v", err)
+	}
+	if got := strings.Join(fake.calls[0], " "); got != "server list --selector clanker.io/cluster=demo -o json" {
+		t.Errorf("args = %s", got)
+	}
+	if len(instances) != 3 {
+		t.Fatalf("got %d instances, want 3", len(instances))
+	}
+	if !instances[0].Running || instances[1].Running {
+		t.Errorf("running states = %v, %v", instances[0].Running, instances[1].Running)
+	}
+	if instances[1].Role != "worker-0" || instances[1].PrivateIP != "" {
+		t.Errorf("worker = %+v", instances[1])
+	}
+}
+
+func TestHetznerBackendListClusters(t *testing.T) {
+	fake := &fakeHcloud{outputs: map[string]string{
+		"server list":  hcloudServersJSON,
+		"network list": `[{"name":"demo-k8s","labels":{"clanker.io/cluster":"demo"}},{"name":"broken-k8s","labels":{"clanker.io/cluster":"broken"}}]`,
+	}}
+	backend := NewHetznerBackend(HetznerBackendOptions{Client: fake})
+
+	names, err := backend.ListClusters(context.Background())
+	if err != nil {
+		t.Fatalf("ListClusters: %v", err)
+	}
+	if strings.Join(names, ",") != "broken,demo,lab" {
+		t.Errorf("names = %v", names)
+	}
+}
+
+func TestHetznerBackendCleanupCluster(t *testing.T) {
+	fake := &fakeHcloud{errs: map[string]error{
+		"firewall delete": errors.New("hcloud: firewall not found (not_found)"),
+	}}
+	backend := NewHetznerBackend(HetznerBackendOptions{Client: fake})
+
+	if err := backend.CleanupCluster(context.Background(), "demo"); err != nil {
+		t.Errorf("CleanupCluster should ignore missing resources: %v", err)
+	}
+	if len(fake.calls) != 2 || strings.Join(fake.calls[1], " ") != "network delete demo-k8s" {
+		t.Errorf("calls = %v", fake.calls)
+	}
+}
+
+func TestHetznerNetworkZone(t *testing.T) {
+	tests := map[string]string{
+		"fsn1": "eu-central",
+		"hel1": "eu-central",
+		"ash":  "us-east",
+		"hil":  "us-west",
+		"sin":  "ap-southeast",
+	}
+	for location, want := range tests {
+		if got := hetznerNetworkZone(location); got != want {
+			t.Errorf("hetznerNetworkZone(%s) = %s, want %s", location, got, want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// pendingPodsCommand lists Pending pods as namespace/name, one per line
+const pendingPodsCommand = `kubectl get pods -A --field-selector=status.phase=Pending -o jsonpath='{range .items[*]}{.metadata.namespace}/{.metadata.name}{"\n"}{end}'`
+
+// PatchNodes applies OS updates to every node and reboots it, one node at a
+// time: workers first, then the control plane nodes with the first one
+// last, so kubectl on it stays available for as long as possible. Each node
+// is drained before its packages are updated and uncordoned once it is back
+// Ready, and the next node only starts when the pods evicted from it are
+// scheduled again. kubelet, kubeadm and kubectl keep their held versions.
+func (p *KubeadmProvider) PatchNodes(ctx context.Context, clusterName string, opts PatchOptions) error {
+	if clusterName == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+
+	instances, err := p.backend.ListInstances(ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to find cluster instances: %w", err)
+	}
+	if len(instances) == 0 {
+		return &ErrClusterNotFound{ClusterName: clusterName}
+	}
+
+	order := kubeadmPatchOrder(instances)
+	if len(order) == 0 || order[len(order)-1].Role != "control-plane" {
+		return fmt.Errorf("no running control plane node found for cluster %s", clusterName)
+	}
+	primary := order[len(order)-1]
+
+	nodesOutput, err := p.runOnNode(ctx, &primary, "kubectl get nodes -o json")
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	nodes, err := parseKubeadmNodes(nodesOutput)
+	if err != nil {
+		return err
+	}
+
+	// Pods that could not be scheduled before patching are not waited for
+	pendingOutput, err := p.runOnNode(ctx, &primary, pendingPodsCommand)
+	if err != nil {
+		return fmt.Errorf("failed to list pending pods: %w", err)
+	}
+	alreadyPending := make(map[string]bool)
+	for _, pod := range strings.Fields(pendingOutput) {
+		alreadyPending[pod] = true
+	}
+
+	for i := range order {
+		inst := order[i]
+		node, ok := nodes[inst.PrivateIP]
+		if !ok {
+			return fmt.Errorf("instance %s (%s) is not registered as a node", inst.Name, inst.PrivateIP)
+		}
+		opts.progress("kubeadm", "patching %s (%d/%d)...", node.Name, i+1, len(order))
+		start := time.Now()
+		if err := p.patchNode(ctx, &primary, &inst, node.Name, alreadyPending, opts); err != nil {
+			return fmt.Errorf("failed to patch %s: %w", node.Name, err)
+		}
+		opts.progress("kubeadm", "%s patched, rebooted and uncordoned in %s", node.Name, time.Since(start).Round(time.Second))
+	}
+
+	return nil
+}
+
+// patchNode drains one node, updates its packages, reboots it and returns
+// it to the scheduler. A node that fails after the drain is left cordoned.
+func (p *KubeadmProvider) patchNode(ctx context.Context, cp, inst *Instance, nodeName string, alreadyPending map[string]bool, opts PatchOptions) error {
+	kubeadmLog.Debugf("draining %s...", nodeName)
+	if _, err := p.runOnNode(ctx, cp, fmt.Sprintf("kubectl drain %s --ignore-daemonsets --delete-emptydir-data --timeout=10m", nodeName)); err != nil {
+		return fmt.Errorf("failed to drain node: %w", err)
+	}
+
+	nodeSSH, err := p.connect(ctx, inst)
+	if err != nil {
+		return fmt.Errorf("node %s left cordoned: %w", nodeName, err)
+	}
+	bootID, err := nodeSSH.Run(ctx, "cat /proc/sys/kernel/random/boot_id")
+	if err != nil {
+		nodeSSH.Close()
+		return fmt.Errorf("failed to read boot ID (node %s left cordoned): %w", nodeName, err)
+	}
+
+	opts.progress("kubeadm", "updating packages on %s...", nodeName)
+	if _, err := nodeSSH.RunSudoScript(ctx, osPatchScript()); err != nil {
+		nodeSSH.Close()
+		return fmt.Errorf("failed to update packages (node %s left cordoned): %w", nodeName, err)
+	}
+
+	// The reboot is scheduled so the command returns before sshd goes away
+	opts.progress("kubeadm", "rebooting %s...", nodeName)
+	_, err = nodeSSH.RunSudo(ctx, "systemd-run --on-active=5 /bin/systemctl reboot")
+	nodeSSH.Close()
+	if err != nil {
+		return fmt.Errorf("failed to reboot (node %s left cordoned): %w", nodeName, err)
+	}
+	if err := p.waitForReboot(ctx, inst, bootID); err != nil {
+		return fmt.Errorf("%w (node %s left cordoned)", err, nodeName)
+	}
+
+	cpSSH, err := p.connect(ctx, cp)
+	if err != nil {
+		return fmt.Errorf("failed to connect to control plane (node %s left cordoned): %w", nodeName, err)
+	}
+	defer cpSSH.Close()
+
+	if err := WaitForNodeReady(ctx, cpSSH, DefaultNodeReadyTimeout); err != nil {
+		return fmt.Errorf("node %s did not become ready (left cordoned): %w", nodeName, err)
+	}
+	if _, err := cpSSH.Run(ctx, "kubectl uncordon "+nodeName); err != nil {
+		return fmt.Errorf("failed to uncordon node: %w", err)
+	}
+
+	opts.progress("kubeadm", "waiting for evicted pods to be scheduled again...")
+	return waitForRescheduled(ctx, cpSSH, alreadyPending, DefaultPodRescheduleTimeout)
+}
+
+// runOnNode runs one command on an instance over a pooled connection
+func (p *KubeadmProvider) runOnNode(ctx context.Context, inst *Instance, command string) (string, error) {
+	ssh, err := p.connect(ctx, inst)
+	if err != nil {
+		return "", err
+	}
+	defer ssh.Close()
+	return ssh.Run(ctx, command)
+}
+
+// waitForReboot waits until the node answers over SSH with a boot ID other
+// than the one it had before the reboot
+func (p *KubeadmProvider) waitForReboot(ctx context.Context, inst *Instance, oldBootID string) error {
+	oldBootID = strings.TrimSpace(oldBootID)
+	deadline := time.Now().Add(DefaultSSHConnectTimeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Second):
+		}
+
+		bootID, err := p.runOnNode(ctx, inst, "cat /proc/sys/kernel/random/boot_id")
+		if err == nil && strings.TrimSpace(bootID) != oldBootID {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("node did not come back from reboot within %s", DefaultSSHConnectTimeout)
+		}
+	}
+}
+
+// waitForRescheduled waits until no pod is Pending apart from those that
+// already were before patching started
+func waitForRescheduled(ctx context.Context, ssh *SSHClient, alreadyPending map[string]bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	var pending []string
+	for {
+		output, err := ssh.Run(ctx, pendingPodsCommand)
+		if err == nil {
+			pending = newlyPendingPods(output, alreadyPending)
+			if len(pending) == 0 {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			if len(pending) == 0 {
+				return fmt.Errorf("could not list pending pods: %w", err)
+			}
+			return fmt.Errorf("pods still pending after %s: %s", timeout, strings.Join(pending, ", "))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// newlyPendingPods returns the pods in pendingPodsCommand output that were
+// not pending before
+func newlyPendingPods(output string, alreadyPending map[string]bool) []string {
+	var pending []string
+	for _, pod := range strings.Fields(output) {
+		if !alreadyPending[pod] {
+			pending = append(pending, pod)
+		}
+	}
+	return pending
+}
+
+// kubeadmPatchOrder returns the running nodes in patch order: the workers,
+// the other control plane nodes, then the first control plane node. It is
+// the reverse of the upgrade order by role.
+func kubeadmPatchOrder(instances []Instance) []Instance {
+	var primary, controlPlanes, workers []Instance
+	for _, inst := range kubeadmUpgradeOrder(instances) {
+		switch {
+		case inst.Role == "control-plane":
+			primary = append(primary, inst)
+		case strings.HasPrefix(inst.Role, "control-plane"):
+			controlPlanes = append(controlPlanes, inst)
+		default:
+			workers = append(workers, inst)
+		}
+	}
+
+	order := append(workers, controlPlanes...)
+	return append(order, primary...)
+}
+
+// osPatchScript installs pending OS updates with the node's package
+// manager. The Kubernetes packages are held on apt and excluded on dnf and
+// yum, so only `clanker k8s upgrade` moves them.
+func osPatchScript() string {
+	return `set -e
+
+if command -v apt-get >/dev/null 2>&1; then
+  export DEBIAN_FRONTEND=noninteractive
+  apt-get update
+  apt-get -y -o Dpkg::Options::=--force-confdef -o Dpkg::Options::=--force-confold dist-upgrade
+  apt-get -y autoremove
+elif command -v dnf >/dev/null 2>&1; then
+  dnf -y upgrade --exclude=kubelet,kubeadm,kubectl,kubernetes-cni,cri-tools
+elif command -v yum >/dev/null 2>&1; then
+  yum -y update --exclude=kubelet,kubeadm,kubectl,kubernetes-cni,cri-tools
+else
+  echo "no supported package manager (apt-get, dnf or yum)" >&2
+  exit 1
+fi
+`
+}
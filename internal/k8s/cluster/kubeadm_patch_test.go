@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKubeadmPatchOrder(t *testing.T) {
+	instances := []Instance{
+		{Name: "c-control-plane", Role: "control-plane", Running: true},
+		{Name: "c-worker-1", Role: "worker-1", Running: true},
+		{Name: "c-lb", Role: "lb", Running: true},
+		{Name: "c-control-plane-2", Role: "control-plane-2", Running: true},
+		{Name: "c-worker-2", Role: "worker-2", Running: false},
+		{Name: "c-worker-3", Role: "worker-3", Running: true},
+	}
+
+	var names []string
+	for _, inst := range kubeadmPatchOrder(instances) {
+		names = append(names, inst.Name)
+	}
+	want := "c-worker-1,c-worker-3,c-control-plane-2,c-control-plane"
+	if strings.Join(names, ",") != want {
+		t.Errorf("order = %v, want %s", names, want)
+	}
+}
+
+func TestNewlyPendingPods(t *testing.T) {
+	already := map[string]bool{"batch/stuck-job-x2k": true}
+	output := "batch/stuck-job-x2k\nshop/web-7d9f-abcde\nshop/worker-0\n"
+
+	got := newlyPendingPods(output, already)
+	if strings.Join(got, ",") != "shop/web-7d9f-abcde,shop/worker-0" {
+		t.Errorf("newlyPendingPods = %v", got)
+	}
+	if got := newlyPendingPods("batch/stuck-job-x2k\n", already); len(got) != 0 {
+		t.Errorf("newlyPendingPods = %v, want none", got)
+	}
+}
+
+func TestOSPatchScript(t *testing.T) {
+	script := osPatchScript()
+	for _, want := range []string{
+		"apt-get update",
+		"dist-upgrade",
+		"dnf -y upgrade --exclude=kubelet,kubeadm,kubectl",
+		"yum -y update --exclude=kubelet,kubeadm,kubectl",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %q", want)
+		}
+	}
+	// apt keeps the Kubernetes packages through their holds
+	if strings.Contains(script, "apt-mark unhold") {
+		t.Error("script must not unhold the Kubernetes packages")
+	}
+}
@@ -12,8 +12,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bgdnvk/clanker/internal/dryrun"
 	"github.com/bgdnvk/clanker/internal/sshknownhosts"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 // SSHClient provides SSH connection and command execution
@@ -22,18 +24,29 @@ type SSHClient struct {
 	port       int
 	user       string
 	privateKey []byte
+	jumpHost   string
 	client     *ssh.Client
 	debug      bool
+
+	// via is the jump host connection the client dials through
+	via *SSHClient
+	// release hands a pooled client back to its SSHPool instead of closing it
+	release func()
 }
 
-// SSHClientOptions contains options for creating an SSH client
+// SSHClientOptions contains options for creating an SSH client. Keys are
+// tried from PrivateKey, PrivateKeyPath or the default ~/.ssh locations, and
+// a running ssh-agent (SSH_AUTH_SOCK) is offered alongside them.
 type SSHClientOptions struct {
 	Host           string
 	Port           int
 	User           string
 	PrivateKeyPath string
 	PrivateKey     []byte
-	Debug          bool
+	// JumpHost is a bastion as [user@]host[:port]; the target host is
+	// dialed through it, so private addresses work
+	JumpHost string
+	Debug    bool
 }
 
 // NewSSHClient creates a new SSH client
@@ -61,8 +74,8 @@ func NewSSHClient(opts SSHClientOptions) (*SSHClient, error) {
 				break
 			}
 		}
-		if len(privateKey) == 0 {
-			return nil, fmt.Errorf("no SSH private key found")
+		if len(privateKey) == 0 && os.Getenv("SSH_AUTH_SOCK") == "" {
+			return nil, fmt.Errorf("no SSH private key found and no ssh-agent running")
 		}
 	}
 
@@ -81,16 +94,69 @@ func NewSSHClient(opts SSHClientOptions) (*SSHClient, error) {
 		port:       port,
 		user:       user,
 		privateKey: privateKey,
+		jumpHost:   opts.JumpHost,
 		debug:      opts.Debug,
 	}, nil
 }
 
-// Connect establishes an SSH connection
+// ParseJumpHost splits a [user@]host[:port] jump host spec, defaulting the
+// user to defaultUser and the port to 22
+func ParseJumpHost(spec, defaultUser string) (user, host string, port int, err error) {
+	user, host = defaultUser, strings.TrimSpace(spec)
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		user, host = host[:at], host[at+1:]
+	}
+	port = 22
+	if h, p, splitErr := net.SplitHostPort(host); splitErr == nil {
+		port, err = strconv.Atoi(p)
+		if err != nil || port <= 0 || port > 65535 {
+			return "", "", 0, fmt.Errorf("invalid jump host port in %q", spec)
+		}
+		host = h
+	}
+	if host == "" || user == "" {
+		return "", "", 0, fmt.Errorf("invalid jump host %q, want [user@]host[:port]", spec)
+	}
+	return user, host, port, nil
+}
+
+// authMethods offers the client's private key and, when SSH_AUTH_SOCK is
+// set, the keys held by ssh-agent. The returned closer releases the agent
+// socket once the handshake is done.
+func (c *SSHClient) authMethods() ([]ssh.AuthMethod, func(), error) {
+	var methods []ssh.AuthMethod
+	if len(c.privateKey) > 0 {
+		signer, err := ssh.ParsePrivateKey(c.privateKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+
+	closer := func() {}
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+			closer = func() { conn.Close() }
+		} else {
+			sshLog.Debugf("ssh-agent unavailable: %v", err)
+		}
+	}
+
+	if len(methods) == 0 {
+		return nil, nil, fmt.Errorf("no SSH private key or ssh-agent keys available")
+	}
+	return methods, closer, nil
+}
+
+// Connect establishes an SSH connection, through the jump host when one
+// is configured
 func (c *SSHClient) Connect(ctx context.Context) error {
-	signer, err := ssh.ParsePrivateKey(c.privateKey)
+	auth, closeAgent, err := c.authMethods()
 	if err != nil {
-		return fmt.Errorf("failed to parse private key: %w", err)
+		return err
 	}
+	defer closeAgent()
 
 	hostKeyCallback, err := sshknownhosts.NewTOFUCallback("")
 	if err != nil {
@@ -98,52 +164,95 @@ func (c *SSHClient) Connect(ctx context.Context) error {
 	}
 
 	config := &ssh.ClientConfig{
-		User: c.user,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
+		User:            c.user,
+		Auth:            auth,
 		HostKeyCallback: hostKeyCallback,
 		Timeout:         30 * time.Second,
 	}
 
-	addr := fmt.Sprintf("%s:%d", c.host, c.port)
-
-	if c.debug {
-		fmt.Printf("[ssh] connecting to %s@%s\n", c.user, addr)
+	// A jump connection opened here is closed on failure; one handed in by
+	// an SSHPool belongs to the pool
+	ownVia := false
+	fail := func(err error) error {
+		if ownVia {
+			c.closeVia()
+		}
+		return err
 	}
+	if c.jumpHost != "" && c.via == nil {
+		jumpUser, jumpHost, jumpPort, err := ParseJumpHost(c.jumpHost, c.user)
+		if err != nil {
+			return err
+		}
+		via := &SSHClient{host: jumpHost, port: jumpPort, user: jumpUser, privateKey: c.privateKey, debug: c.debug}
+		if err := via.Connect(ctx); err != nil {
+			return fmt.Errorf("jump host %s: %w", c.jumpHost, err)
+		}
+		c.via, ownVia = via, true
+	}
+
+	addr := net.JoinHostPort(c.host, strconv.Itoa(c.port))
 
-	// Use context for connection timeout
-	var dialer net.Dialer
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	var conn net.Conn
+	if c.via != nil {
+		sshLog.Debugf("connecting to %s@%s via %s", c.user, addr, c.via.host)
+		conn, err = c.via.client.DialContext(ctx, "tcp", addr)
+	} else {
+		sshLog.Debugf("connecting to %s@%s", c.user, addr)
+		// Use context for connection timeout
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to dial: %w", err)
+		return fail(fmt.Errorf("failed to dial: %w", err))
 	}
 
 	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
 	if err != nil {
 		conn.Close()
-		return fmt.Errorf("failed to create SSH connection: %w", err)
+		return fail(fmt.Errorf("failed to create SSH connection: %w", err))
 	}
 
 	c.client = ssh.NewClient(sshConn, chans, reqs)
 
-	if c.debug {
-		fmt.Printf("[ssh] connected to %s\n", addr)
-	}
+	sshLog.Debugf("connected to %s", addr)
 
 	return nil
 }
 
-// Close closes the SSH connection
+// Close closes the SSH connection and its jump host connection. A client
+// from an SSHPool is handed back to the pool instead.
 func (c *SSHClient) Close() error {
+	if c.release != nil {
+		release := c.release
+		c.release = nil
+		release()
+		return nil
+	}
+	var err error
 	if c.client != nil {
-		return c.client.Close()
+		err = c.client.Close()
+		c.client = nil
+	}
+	c.closeVia()
+	return err
+}
+
+func (c *SSHClient) closeVia() {
+	if c.via != nil {
+		_ = c.via.Close()
+		c.via = nil
 	}
-	return nil
 }
 
 // Run executes a command on the remote host
 func (c *SSHClient) Run(ctx context.Context, command string) (string, error) {
+	// Bootstrap commands are shell strings that cannot be told apart from
+	// lookups, so a dry run stops at the first one
+	if dryrun.Enabled() {
+		dryrun.PrintScript(os.Stdout, c.user+"@"+c.host, command)
+		return "", dryrun.ErrStopped
+	}
 	if c.client == nil {
 		return "", fmt.Errorf("not connected")
 	}
@@ -154,9 +263,7 @@ func (c *SSHClient) Run(ctx context.Context, command string) (string, error) {
 	}
 	defer session.Close()
 
-	if c.debug {
-		fmt.Printf("[ssh] running: %s\n", command)
-	}
+	sshLog.Debugf("running: %s", command)
 
 	var stdout, stderr bytes.Buffer
 	session.Stdout = &stdout
@@ -216,6 +323,10 @@ func (c *SSHClient) Upload(ctx context.Context, localPath, remotePath string) er
 
 // UploadBytes copies bytes to a remote file
 func (c *SSHClient) UploadBytes(ctx context.Context, data []byte, remotePath string) error {
+	if dryrun.Enabled() {
+		dryrun.PrintInput(os.Stdout, "scp", []string{"-t", c.user + "@" + c.host + ":" + remotePath}, fmt.Sprintf("%d bytes", len(data)))
+		return dryrun.ErrStopped
+	}
 	if c.client == nil {
 		return fmt.Errorf("not connected")
 	}
@@ -226,9 +337,7 @@ func (c *SSHClient) UploadBytes(ctx context.Context, data []byte, remotePath str
 	}
 	defer session.Close()
 
-	if c.debug {
-		fmt.Printf("[ssh] uploading %d bytes to %s\n", len(data), remotePath)
-	}
+	sshLog.Debugf("uploading %d bytes to %s", len(data), remotePath)
 
 	// Use stdin to pipe the file content
 	go func() {
@@ -255,9 +364,7 @@ func (c *SSHClient) Download(ctx context.Context, remotePath, localPath string)
 	}
 	defer session.Close()
 
-	if c.debug {
-		fmt.Printf("[ssh] downloading %s to %s\n", remotePath, localPath)
-	}
+	sshLog.Debugf("downloading %s to %s", remotePath, localPath)
 
 	var stdout bytes.Buffer
 	session.Stdout = &stdout
@@ -281,9 +388,7 @@ func (c *SSHClient) DownloadBytes(ctx context.Context, remotePath string) ([]byt
 	}
 	defer session.Close()
 
-	if c.debug {
-		fmt.Printf("[ssh] reading %s\n", remotePath)
-	}
+	sshLog.Debugf("reading %s", remotePath)
 
 	var stdout bytes.Buffer
 	session.Stdout = &stdout
@@ -0,0 +1,189 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultSSHIdleTimeout is how long an unused pooled connection stays open
+const DefaultSSHIdleTimeout = 2 * time.Minute
+
+// SSHPool reuses SSH connections across kubeadm operations. Clients are
+// keyed by user, host, port and jump host; Close on a pooled client hands
+// it back, and a connection nobody holds is closed after the idle timeout.
+// Connections to the same jump host are shared by every node behind it.
+type SSHPool struct {
+	mu    sync.Mutex
+	conns map[string]*pooledSSH
+	idle  time.Duration
+
+	// dial connects a new client; replaced in tests
+	dial func(ctx context.Context, c *SSHClient) error
+}
+
+type pooledSSH struct {
+	client *SSHClient
+	refs   int
+	timer  *time.Timer
+
+	// jump is the pooled jump host connection this one is dialed through
+	jump    *pooledSSH
+	jumpKey string
+}
+
+// NewSSHPool creates a pool that closes connections idle for longer than
+// idle, or DefaultSSHIdleTimeout when idle is zero
+func NewSSHPool(idle time.Duration) *SSHPool {
+	if idle <= 0 {
+		idle = DefaultSSHIdleTimeout
+	}
+	return &SSHPool{
+		conns: make(map[string]*pooledSSH),
+		idle:  idle,
+		dial:  func(ctx context.Context, c *SSHClient) error { return c.Connect(ctx) },
+	}
+}
+
+// Get returns a connected client for opts, reusing an open connection when
+// one is still alive. The caller must Close it when done.
+func (p *SSHPool) Get(ctx context.Context, opts SSHClientOptions) (*SSHClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key, pc, err := p.getLocked(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	pc.acquire()
+
+	handle := *pc.client
+	handle.via = nil
+	handle.release = func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.releaseLocked(key, pc)
+	}
+	return &handle, nil
+}
+
+func (p *SSHPool) getLocked(ctx context.Context, opts SSHClientOptions) (string, *pooledSSH, error) {
+	client, err := NewSSHClient(opts)
+	if err != nil {
+		return "", nil, err
+	}
+	key := sshPoolKey(client)
+
+	if pc := p.conns[key]; pc != nil {
+		if pc.alive() {
+			return key, pc, nil
+		}
+		sshLog.Debugf("pooled connection to %s is gone, reconnecting", key)
+		delete(p.conns, key)
+		p.closeLocked(pc)
+	}
+
+	pc := &pooledSSH{client: client}
+	if client.jumpHost != "" {
+		jumpUser, jumpHost, jumpPort, err := ParseJumpHost(client.jumpHost, client.user)
+		if err != nil {
+			return "", nil, err
+		}
+		jumpKey, jump, err := p.getLocked(ctx, SSHClientOptions{
+			Host:       jumpHost,
+			Port:       jumpPort,
+			User:       jumpUser,
+			PrivateKey: client.privateKey,
+			Debug:      client.debug,
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("jump host %s: %w", client.jumpHost, err)
+		}
+		jump.acquire()
+		pc.jump, pc.jumpKey = jump, jumpKey
+		client.via = jump.client
+	}
+
+	if err := p.dial(ctx, client); err != nil {
+		if pc.jump != nil {
+			client.via = nil
+			p.releaseLocked(pc.jumpKey, pc.jump)
+		}
+		return "", nil, err
+	}
+
+	p.conns[key] = pc
+	return key, pc, nil
+}
+
+func (pc *pooledSSH) acquire() {
+	pc.refs++
+	if pc.timer != nil {
+		pc.timer.Stop()
+		pc.timer = nil
+	}
+}
+
+// releaseLocked drops a reference and closes the connection once it has
+// been idle for the pool's timeout
+func (p *SSHPool) releaseLocked(key string, pc *pooledSSH) {
+	pc.refs--
+	if pc.refs > 0 || p.conns[key] != pc {
+		return
+	}
+	pc.timer = time.AfterFunc(p.idle, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if pc.refs == 0 && p.conns[key] == pc {
+			delete(p.conns, key)
+			p.closeLocked(pc)
+		}
+	})
+}
+
+// closeLocked closes a connection that is no longer in the pool and
+// releases its jump host connection
+func (p *SSHPool) closeLocked(pc *pooledSSH) {
+	if pc.timer != nil {
+		pc.timer.Stop()
+		pc.timer = nil
+	}
+	if pc.client.client != nil {
+		_ = pc.client.client.Close()
+		pc.client.client = nil
+	}
+	pc.client.via = nil
+	if pc.jump != nil {
+		p.releaseLocked(pc.jumpKey, pc.jump)
+		pc.jump = nil
+	}
+}
+
+// Close closes every pooled connection, including ones still handed out
+func (p *SSHPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, pc := range p.conns {
+		delete(p.conns, key)
+		p.closeLocked(pc)
+	}
+}
+
+// alive checks the connection with a keepalive request
+func (pc *pooledSSH) alive() bool {
+	if pc.client.client == nil {
+		return false
+	}
+	_, _, err := pc.client.client.SendRequest("keepalive@openssh.com", true, nil)
+	return err == nil
+}
+
+func sshPoolKey(c *SSHClient) string {
+	key := c.user + "@" + c.host + ":" + strconv.Itoa(c.port)
+	if c.jumpHost != "" {
+		key += " via " + c.jumpHost
+	}
+	return key
+}
@@ -0,0 +1,298 @@
+package cluster
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testSSHServer answers exec requests with "<user> ran <command>" and
+// forwards direct-tcpip channels, so it can act as a node or a jump host
+type testSSHServer struct {
+	host     string
+	port     int
+	conns    atomic.Int32
+	forwards atomic.Int32
+}
+
+func startTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return &ssh.Permissions{}, nil
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	addr := ln.Addr().(*net.TCPAddr)
+	srv := &testSSHServer{host: addr.IP.String(), port: addr.Port}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			srv.conns.Add(1)
+			go srv.serve(conn, config)
+		}
+	}()
+	return srv
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		switch newChan.ChannelType() {
+		case "session":
+			ch, chReqs, err := newChan.Accept()
+			if err != nil {
+				continue
+			}
+			go func() {
+				defer ch.Close()
+				for req := range chReqs {
+					if req.Type != "exec" {
+						_ = req.Reply(false, nil)
+						continue
+					}
+					var exec struct{ Command string }
+					_ = ssh.Unmarshal(req.Payload, &exec)
+					_ = req.Reply(true, nil)
+					_, _ = io.WriteString(ch, sconn.User()+" ran "+exec.Command)
+					_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+					return
+				}
+			}()
+		case "direct-tcpip":
+			var target struct {
+				Host     string
+				Port     uint32
+				OrigHost string
+				OrigPort uint32
+			}
+			_ = ssh.Unmarshal(newChan.ExtraData(), &target)
+			upstream, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+			if err != nil {
+				_ = newChan.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			ch, chReqs, err := newChan.Accept()
+			if err != nil {
+				upstream.Close()
+				continue
+			}
+			s.forwards.Add(1)
+			go ssh.DiscardRequests(chReqs)
+			go func() {
+				var wg sync.WaitGroup
+				wg.Add(2)
+				go func() { defer wg.Done(); _, _ = io.Copy(ch, upstream); _ = ch.CloseWrite() }()
+				go func() { defer wg.Done(); _, _ = io.Copy(upstream, ch) }()
+				wg.Wait()
+				ch.Close()
+				upstream.Close()
+			}()
+		default:
+			_ = newChan.Reject(ssh.UnknownChannelType, "unsupported")
+		}
+	}
+}
+
+// testSSHKey isolates known_hosts and ssh-agent from the user's own and
+// returns a fresh client key
+func testSSHKey(t *testing.T) []byte {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("SSH_AUTH_SOCK", "")
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(block)
+}
+
+func TestSSHPoolReusesConnections(t *testing.T) {
+	key := testSSHKey(t)
+	node := startTestSSHServer(t)
+	pool := NewSSHPool(time.Minute)
+	defer pool.Close()
+	ctx := context.Background()
+	opts := SSHClientOptions{Host: node.host, Port: node.port, User: "ubuntu", PrivateKey: key}
+
+	first, err := pool.Get(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := pool.Get(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := second.Run(ctx, "hostname")
+	if err != nil || out != "ubuntu ran hostname" {
+		t.Fatalf("Run = %q, %v", out, err)
+	}
+	first.Close()
+	second.Close()
+
+	// A released connection stays open for the next operation
+	third, err := pool.Get(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := third.Run(ctx, "uptime"); err != nil {
+		t.Fatal(err)
+	}
+	third.Close()
+	if got := node.conns.Load(); got != 1 {
+		t.Errorf("connections = %d, want 1", got)
+	}
+
+	opts.User = "admin"
+	other, err := pool.Get(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Close()
+	if got := node.conns.Load(); got != 2 {
+		t.Errorf("connections after user change = %d, want 2", got)
+	}
+}
+
+func TestSSHPoolClosesIdleConnections(t *testing.T) {
+	key := testSSHKey(t)
+	node := startTestSSHServer(t)
+	pool := NewSSHPool(10 * time.Millisecond)
+	defer pool.Close()
+	ctx := context.Background()
+	opts := SSHClientOptions{Host: node.host, Port: node.port, User: "ubuntu", PrivateKey: key}
+
+	client, err := pool.Get(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		pool.mu.Lock()
+		open := len(pool.conns)
+		pool.mu.Unlock()
+		if open == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("idle connection was not closed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	client, err = pool.Get(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	if got := node.conns.Load(); got != 2 {
+		t.Errorf("connections = %d, want 2", got)
+	}
+}
+
+func TestSSHPoolSharesJumpHost(t *testing.T) {
+	key := testSSHKey(t)
+	bastion := startTestSSHServer(t)
+	nodeA := startTestSSHServer(t)
+	nodeB := startTestSSHServer(t)
+	pool := NewSSHPool(time.Minute)
+	defer pool.Close()
+	ctx := context.Background()
+	jump := "jump@" + net.JoinHostPort(bastion.host, strconv.Itoa(bastion.port))
+
+	for _, node := range []*testSSHServer{nodeA, nodeB} {
+		client, err := pool.Get(ctx, SSHClientOptions{Host: node.host, Port: node.port, User: "ubuntu", PrivateKey: key, JumpHost: jump})
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := client.Run(ctx, "kubeadm version")
+		if err != nil || out != "ubuntu ran kubeadm version" {
+			t.Fatalf("Run = %q, %v", out, err)
+		}
+		client.Close()
+	}
+
+	if got := bastion.conns.Load(); got != 1 {
+		t.Errorf("jump host connections = %d, want 1", got)
+	}
+	if got := bastion.forwards.Load(); got != 2 {
+		t.Errorf("forwarded connections = %d, want 2", got)
+	}
+
+	// Without the pool the client opens its own jump connection
+	direct, err := NewSSHClient(SSHClientOptions{Host: nodeA.host, Port: nodeA.port, PrivateKey: key, JumpHost: jump})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := direct.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	direct.Close()
+	if got := bastion.conns.Load(); got != 2 {
+		t.Errorf("jump host connections = %d, want 2", got)
+	}
+}
+
+func TestParseJumpHost(t *testing.T) {
+	tests := []struct {
+		spec, user, host string
+		port             int
+		wantErr          bool
+	}{
+		{"bastion.example.com", "ubuntu", "bastion.example.com", 22, false},
+		{"ec2-user@10.0.0.5", "ec2-user", "10.0.0.5", 22, false},
+		{"admin@bastion:2222", "admin", "bastion", 2222, false},
+		{"[fd00::1]:2200", "ubuntu", "fd00::1", 2200, false},
+		{"bastion:ssh", "", "", 0, true},
+		{"admin@", "", "", 0, true},
+	}
+	for _, tt := range tests {
+		user, host, port, err := ParseJumpHost(tt.spec, "ubuntu")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseJumpHost(%q) err = %v", tt.spec, err)
+			continue
+		}
+		if user != tt.user || host != tt.host || port != tt.port {
+			t.Errorf("ParseJumpHost(%q) = %s, %s, %d", tt.spec, user, host, port)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+)
+
+// KubeadmCreateStep is the last completed stage of kubeadm cluster creation
+type KubeadmCreateStep string
+
+const (
+	KubeadmStepStarted                 KubeadmCreateStep = "started"
+	KubeadmStepNetworkPrepared         KubeadmCreateStep = "network-prepared"
+	KubeadmStepControlPlaneLaunched    KubeadmCreateStep = "control-plane-launched"
+	KubeadmStepControlPlaneInitialized KubeadmCreateStep = "control-plane-initialized"
+	KubeadmStepCNIInstalled            KubeadmCreateStep = "cni-installed"
+	KubeadmStepComplete                KubeadmCreateStep = "complete"
+)
+
+// kubeadmStepOrder ranks the steps so a resume can skip completed ones
+var kubeadmStepOrder = map[KubeadmCreateStep]int{
+	KubeadmStepStarted:                 0,
+	KubeadmStepNetworkPrepared:         1,
+	KubeadmStepControlPlaneLaunched:    2,
+	KubeadmStepControlPlaneInitialized: 3,
+	KubeadmStepCNIInstalled:            4,
+	KubeadmStepComplete:                5,
+}
+
+// KubeadmCreateState records how far Create got, so an interrupted creation
+// can continue with Resume instead of starting over. It is kept in
+// ~/.clanker/clusters/<name>.json until the cluster is complete.
+type KubeadmCreateState struct {
+	ClusterName string            `json:"clusterName"`
+	Backend     string            `json:"backend"`
+	Region      string            `json:"region"`
+	Step        KubeadmCreateStep `json:"step"`
+
+	// Options Create was called with; Resume reuses them
+	KubernetesVersion string            `json:"kubernetesVersion,omitempty"`
+	ControlPlaneCount int               `json:"controlPlaneCount,omitempty"`
+	ControlPlaneType  string            `json:"controlPlaneType,omitempty"`
+	WorkerCount       int               `json:"workerCount"`
+	WorkerType        string            `json:"workerType,omitempty"`
+	Tags              map[string]string `json:"tags,omitempty"`
+
+	// Network is the handle PrepareCluster returned
+	Network      string    `json:"network,omitempty"`
+	ControlPlane *Instance `json:"controlPlane,omitempty"`
+	// LoadBalancer and ControlPlanes (the additional control plane nodes)
+	// are only used by HA clusters
+	LoadBalancer  *Instance          `json:"loadBalancer,omitempty"`
+	ControlPlanes []KubeadmNodeState `json:"controlPlanes,omitempty"`
+	Workers       []KubeadmNodeState `json:"workers,omitempty"`
+
+	LastError string    `json:"lastError,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// KubeadmNodeState tracks one launched node that joins the cluster
+type KubeadmNodeState struct {
+	Instance Instance `json:"instance"`
+	Joined   bool     `json:"joined"`
+}
+
+// Reached reports whether step has already been completed
+func (s *KubeadmCreateState) Reached(step KubeadmCreateStep) bool {
+	return kubeadmStepOrder[s.Step] >= kubeadmStepOrder[step]
+}
+
+// HA reports whether the cluster has more than one control plane node
+func (s *KubeadmCreateState) HA() bool {
+	return s.ControlPlaneCount > 1
+}
+
+// node returns the tracked joining node with the given role, if any
+func (s *KubeadmCreateState) node(role string) *KubeadmNodeState {
+	for _, nodes := range [][]KubeadmNodeState{s.ControlPlanes, s.Workers} {
+		for i := range nodes {
+			if nodes[i].Instance.Role == role {
+				return &nodes[i]
+			}
+		}
+	}
+	return nil
+}
+
+// dropNode forgets a node whose instance no longer exists
+func (s *KubeadmCreateState) dropNode(role string) {
+	s.ControlPlanes = dropNodeRole(s.ControlPlanes, role)
+	s.Workers = dropNodeRole(s.Workers, role)
+}
+
+func dropNodeRole(nodes []KubeadmNodeState, role string) []KubeadmNodeState {
+	kept := nodes[:0]
+	for _, n := range nodes {
+		if n.Instance.Role != role {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
+
+// kubeadmStateDir returns ~/.clanker/clusters
+func kubeadmStateDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "clusters"), nil
+}
+
+func kubeadmStatePath(clusterName string) (string, error) {
+	dir, err := kubeadmStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, secfile.SafeSlug(clusterName)+".json"), nil
+}
+
+// SaveKubeadmCreateState writes the creation state for its cluster
+func SaveKubeadmCreateState(state *KubeadmCreateState) error {
+	path, err := kubeadmStatePath(state.ClusterName)
+	if err != nil {
+		return err
+	}
+	if err := secfile.EnsurePrivateDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to create cluster state directory: %w", err)
+	}
+
+	state.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cluster state: %w", err)
+	}
+
+	// Write then rename so an interrupted write never leaves a torn file
+	tmpPath := path + ".tmp"
+	if err := secfile.WritePrivate(tmpPath, data); err != nil {
+		return fmt.Errorf("failed to write cluster state: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write cluster state: %w", err)
+	}
+	return nil
+}
+
+// LoadKubeadmCreateState reads the creation state of a cluster. It returns
+// nil without an error when there is none.
+func LoadKubeadmCreateState(clusterName string) (*KubeadmCreateState, error) {
+	path, err := kubeadmStatePath(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	data, err := secfile.ReadPrivate(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cluster state: %w", err)
+	}
+
+	var state KubeadmCreateState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster state %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// DeleteKubeadmCreateState removes the creation state of a cluster
+func DeleteKubeadmCreateState(clusterName string) error {
+	path, err := kubeadmStatePath(clusterName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
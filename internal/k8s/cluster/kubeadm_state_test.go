@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKubeadmCreateStateRoundTrip(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	state, err := LoadKubeadmCreateState("demo")
+	if err != nil || state != nil {
+		t.Fatalf("LoadKubeadmCreateState before save = %v, %v; want nil, nil", state, err)
+	}
+
+	saved := &KubeadmCreateState{
+		ClusterName:  "demo",
+		Backend:      "hetzner",
+		Region:       "fsn1",
+		Step:         KubeadmStepCNIInstalled,
+		WorkerCount:  2,
+		Network:      "demo-k8s",
+		ControlPlane: &Instance{ID: "101", Role: "control-plane", PublicIP: "203.0.113.10", PrivateIP: "10.0.1.2", Running: true},
+		Workers: []KubeadmNodeState{
+			{Instance: Instance{ID: "102", Role: "worker-0"}, Joined: true},
+			{Instance: Instance{ID: "103", Role: "worker-1"}},
+		},
+		LastError: "worker-1: SSH timeout",
+	}
+	if err := SaveKubeadmCreateState(saved); err != nil {
+		t.Fatalf("SaveKubeadmCreateState: %v", err)
+	}
+
+	path := filepath.Join(home, ".clanker", "clusters", "demo.json")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("state file not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("state file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	loaded, err := LoadKubeadmCreateState("demo")
+	if err != nil {
+		t.Fatalf("LoadKubeadmCreateState: %v", err)
+	}
+	if loaded.Step != KubeadmStepCNIInstalled || loaded.Network != "demo-k8s" || loaded.ControlPlane.PrivateIP != "10.0.1.2" {
+		t.Errorf("loaded state = %+v", loaded)
+	}
+	if w := loaded.node("worker-0"); w == nil || !w.Joined {
+		t.Errorf("worker-0 = %+v, want joined", w)
+	}
+	if w := loaded.node("worker-1"); w == nil || w.Joined {
+		t.Errorf("worker-1 = %+v, want not joined", w)
+	}
+
+	if err := DeleteKubeadmCreateState("demo"); err != nil {
+		t.Fatalf("DeleteKubeadmCreateState: %v", err)
+	}
+	if err := DeleteKubeadmCreateState("demo"); err != nil {
+		t.Errorf("deleting missing state should succeed: %v", err)
+	}
+}
+
+func TestKubeadmCreateStateReached(t *testing.T) {
+	state := &KubeadmCreateState{Step: KubeadmStepControlPlaneInitialized}
+
+	if !state.Reached(KubeadmStepNetworkPrepared) || !state.Reached(KubeadmStepControlPlaneInitialized) {
+		t.Error("earlier steps should be reached")
+	}
+	if state.Reached(KubeadmStepCNIInstalled) || state.Reached(KubeadmStepComplete) {
+		t.Error("later steps should not be reached")
+	}
+}
+
+func TestKubeadmCreateStateDropNode(t *testing.T) {
+	state := &KubeadmCreateState{
+		ControlPlanes: []KubeadmNodeState{
+			{Instance: Instance{ID: "3", Role: "control-plane-1"}},
+		},
+		Workers: []KubeadmNodeState{
+			{Instance: Instance{ID: "1", Role: "worker-0"}},
+			{Instance: Instance{ID: "2", Role: "worker-1"}},
+		},
+	}
+
+	state.dropNode("worker-0")
+	if len(state.Workers) != 1 || state.node("worker-0") != nil || state.node("worker-1") == nil {
+		t.Errorf("workers after drop = %+v", state.Workers)
+	}
+
+	state.dropNode("control-plane-1")
+	if len(state.ControlPlanes) != 0 || len(state.Workers) != 1 {
+		t.Errorf("control planes after drop = %+v", state.ControlPlanes)
+	}
+}
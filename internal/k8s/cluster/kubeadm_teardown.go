@@ -0,0 +1,175 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PreviewDelete lists the instances, the network and firewall (or security
+// group) and the saved creation state that Delete would remove
+func (p *KubeadmProvider) PreviewDelete(ctx context.Context, clusterName string) (*TeardownReport, error) {
+	if clusterName == "" {
+		return nil, &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+
+	instances, err := p.backend.ListInstances(ctx, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cluster instances: %w", err)
+	}
+
+	report := &TeardownReport{ClusterName: clusterName, ClusterType: ClusterTypeKubeadm}
+	for _, inst := range instances {
+		detail := inst.Role
+		if addr := inst.nodeAddress(); addr != "" {
+			detail += ", " + addr
+		}
+		report.Resources = append(report.Resources, TeardownResource{Type: "instance", ID: inst.ID, Name: inst.Name, Detail: detail})
+	}
+
+	if network, err := p.backend.FindCluster(ctx, clusterName); err == nil {
+		report.Resources = append(report.Resources, kubeadmNetworkResources(p.backend.Name(), clusterName, network)...)
+	}
+
+	state, err := LoadKubeadmCreateState(clusterName)
+	if err != nil {
+		report.Warnings = append(report.Warnings, err.Error())
+	}
+	if state != nil {
+		path, _ := kubeadmStatePath(clusterName)
+		report.Resources = append(report.Resources, TeardownResource{
+			Type:   "local-state",
+			ID:     path,
+			Detail: fmt.Sprintf("creation stopped after step %s", state.Step),
+		})
+	}
+
+	if len(report.Resources) == 0 {
+		return nil, &ErrClusterNotFound{ClusterName: clusterName}
+	}
+	return report, nil
+}
+
+// FindOrphans finds what failed creations left behind: a network or
+// security group without instances, instances without a control plane node,
+// and the instances of a creation that stopped with an error. A failed
+// creation can also be finished with Resume instead of being swept.
+func (p *KubeadmProvider) FindOrphans(ctx context.Context) ([]OrphanResource, error) {
+	names, err := p.backend.ListClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []OrphanResource
+	for _, name := range names {
+		instances, err := p.backend.ListInstances(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list instances of %s: %w", name, err)
+		}
+		network, netErr := p.backend.FindCluster(ctx, name)
+		state, _ := LoadKubeadmCreateState(name)
+		if state != nil && state.Backend != p.backend.Name() {
+			state = nil
+		}
+
+		reason := kubeadmOrphanReason(instances, netErr == nil, state)
+		if reason == "" {
+			continue
+		}
+		for _, inst := range instances {
+			orphans = append(orphans, OrphanResource{Type: "instance", ID: inst.ID, Name: inst.Name, Cluster: name, Reason: reason})
+		}
+		if netErr == nil {
+			for _, res := range kubeadmNetworkResources(p.backend.Name(), name, network) {
+				orphans = append(orphans, OrphanResource{Type: res.Type, ID: res.ID, Name: res.Name, Cluster: name, Reason: reason})
+			}
+		}
+	}
+	return orphans, nil
+}
+
+// DeleteOrphans terminates the selected instances, then removes the network
+// and the creation state of every cluster whose network was selected
+func (p *KubeadmProvider) DeleteOrphans(ctx context.Context, orphans []OrphanResource) error {
+	var errs []error
+	var cleanup []string
+	seen := make(map[string]bool)
+	terminated := false
+
+	for _, orphan := range sortOrphansForDeletion(orphans) {
+		switch orphan.Type {
+		case "instance":
+			kubeadmLog.Debugf("terminating instance %s", orphan.ID)
+			if err := p.backend.Terminate(ctx, orphan.ID); err != nil {
+				errs = append(errs, fmt.Errorf("failed to terminate %s: %w", orphan.ID, err))
+				continue
+			}
+			terminated = true
+		case "security-group", "network", "firewall":
+			if !seen[orphan.Cluster] {
+				seen[orphan.Cluster] = true
+				cleanup = append(cleanup, orphan.Cluster)
+			}
+		default:
+			errs = append(errs, fmt.Errorf("cannot delete %s %s: unsupported resource type", orphan.Type, orphan.ID))
+		}
+	}
+
+	if len(cleanup) > 0 && terminated {
+		// Wait for instances to terminate, as Delete does
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(DefaultPollInterval):
+		}
+	}
+
+	for _, name := range cleanup {
+		if err := p.backend.CleanupCluster(ctx, name); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete network of %s: %w", name, err))
+			continue
+		}
+		if err := DeleteKubeadmCreateState(name); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove creation state of %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// kubeadmOrphanReason explains why a cluster's resources are orphaned, or
+// returns "" for a cluster that looks healthy
+func kubeadmOrphanReason(instances []Instance, hasNetwork bool, state *KubeadmCreateState) string {
+	hasControlPlane := false
+	for _, inst := range instances {
+		if strings.HasPrefix(inst.Role, "control-plane") {
+			hasControlPlane = true
+		}
+	}
+
+	switch {
+	case state != nil && !state.Reached(KubeadmStepComplete) && state.LastError != "":
+		return fmt.Sprintf("creation failed after step %s (%s); --resume can finish it instead", state.Step, state.LastError)
+	case len(instances) == 0 && hasNetwork:
+		return "no instances; left by a failed creation or deletion"
+	case len(instances) > 0 && !hasControlPlane:
+		return "no control plane node"
+	}
+	return ""
+}
+
+// kubeadmNetworkResources describes the handle PrepareCluster returned
+func kubeadmNetworkResources(backend, clusterName, handle string) []TeardownResource {
+	switch backend {
+	case "ec2":
+		return []TeardownResource{{Type: "security-group", ID: handle, Name: clusterName + "-k8s-sg"}}
+	case "hetzner":
+		return []TeardownResource{
+			{Type: "firewall", ID: handle, Name: handle},
+			{Type: "network", ID: handle, Name: handle},
+		}
+	default:
+		return []TeardownResource{{Type: "network", ID: handle, Name: handle}}
+	}
+}
@@ -20,16 +20,67 @@ func TestNewKubeadmProvider(t *testing.T) {
 		t.Errorf("Type() = %v, want %v", provider.Type(), ClusterTypeKubeadm)
 	}
 
-	if provider.awsProfile != "test" {
-		t.Errorf("awsProfile = %s, want test", provider.awsProfile)
+	backend, ok := provider.Backend().(*ec2Backend)
+	if !ok {
+		t.Fatalf("Backend() = %T, want *ec2Backend", provider.Backend())
 	}
 
-	if provider.region != "us-west-2" {
-		t.Errorf("region = %s, want us-west-2", provider.region)
+	if backend.awsProfile != "test" {
+		t.Errorf("awsProfile = %s, want test", backend.awsProfile)
 	}
 
-	if provider.keyPairName != "my-key" {
-		t.Errorf("keyPairName = %s, want my-key", provider.keyPairName)
+	if backend.region != "us-west-2" {
+		t.Errorf("region = %s, want us-west-2", backend.region)
+	}
+
+	if backend.keyPairName != "my-key" {
+		t.Errorf("keyPairName = %s, want my-key", backend.keyPairName)
+	}
+}
+
+func TestNewKubeadmProviderWithBackend(t *testing.T) {
+	backend := NewHetznerBackend(HetznerBackendOptions{SSHKeyName: "my-key"})
+	provider := NewKubeadmProvider(KubeadmProviderOptions{Backend: backend})
+
+	if provider.Backend() != backend {
+		t.Error("Backend() did not return the configured backend")
+	}
+}
+
+func TestKubeadmProviderSSHTarget(t *testing.T) {
+	backend := NewHetznerBackend(HetznerBackendOptions{SSHKeyName: "my-key"})
+	direct := NewKubeadmProvider(KubeadmProviderOptions{Backend: backend})
+	if opts := direct.sshOptions("1.2.3.4"); opts.User != "root" || opts.JumpHost != "" {
+		t.Errorf("default sshOptions = %+v", opts)
+	}
+	if got := direct.sshAddress("1.2.3.4", "10.0.0.2"); got != "1.2.3.4" {
+		t.Errorf("sshAddress = %s, want the public address", got)
+	}
+
+	bastioned := NewKubeadmProvider(KubeadmProviderOptions{Backend: backend, SSHUser: "admin", JumpHost: "bastion.example.com"})
+	if opts := bastioned.sshOptions("10.0.0.2"); opts.User != "admin" || opts.JumpHost != "bastion.example.com" {
+		t.Errorf("sshOptions = %+v", opts)
+	}
+	if got := bastioned.sshAddress("1.2.3.4", "10.0.0.2"); got != "10.0.0.2" {
+		t.Errorf("sshAddress = %s, want the private address behind the jump host", got)
+	}
+	if got := bastioned.sshAddress("1.2.3.4", ""); got != "1.2.3.4" {
+		t.Errorf("sshAddress without private IP = %s", got)
+	}
+}
+
+func TestKubeadmInitScriptPrivateNetwork(t *testing.T) {
+	config := DefaultBootstrapConfig()
+	config.NodeIP = "10.0.1.2"
+	config.APIServerSANs = []string{"203.0.113.10", "10.0.1.2"}
+	script := kubeadmInitScript(config)
+
+	if !containsStr(script, "--apiserver-advertise-address=10.0.1.2") {
+		t.Error("script missing advertise address")
+	}
+
+	if !containsStr(script, "--apiserver-cert-extra-sans=203.0.113.10,10.0.1.2") {
+		t.Errorf("script missing API server SANs:\n%s", script)
 	}
 }
 
@@ -0,0 +1,264 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// kubeadmNode is a registered node as the API server reports it
+type kubeadmNode struct {
+	Name           string
+	KubeletVersion string
+}
+
+// Upgrade follows the kubeadm upgrade procedure over SSH: kubeadm upgrade
+// apply on the first control plane node, kubeadm upgrade node on the other
+// control plane nodes and workers, and kubelet/kubectl upgraded one drained
+// node at a time. Nodes whose kubelet is already on targetVersion are
+// skipped, so a failed upgrade can be rerun.
+func (p *KubeadmProvider) Upgrade(ctx context.Context, clusterName, targetVersion string) error {
+	if clusterName == "" {
+		return &ErrInvalidConfiguration{Message: "cluster name is required"}
+	}
+	target, err := KubernetesMinor(targetVersion)
+	if err != nil {
+		return &ErrInvalidConfiguration{Message: err.Error()}
+	}
+
+	instances, err := p.backend.ListInstances(ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to find cluster instances: %w", err)
+	}
+	if len(instances) == 0 {
+		return &ErrClusterNotFound{ClusterName: clusterName}
+	}
+
+	order := kubeadmUpgradeOrder(instances)
+	if len(order) == 0 || order[0].Role != "control-plane" {
+		return fmt.Errorf("no running control plane node found for cluster %s", clusterName)
+	}
+	primary := order[0]
+
+	cpSSH, err := p.connect(ctx, &primary)
+	if err != nil {
+		return fmt.Errorf("failed to connect to control plane: %w", err)
+	}
+	defer cpSSH.Close()
+
+	versionOutput, err := cpSSH.Run(ctx, "kubectl version -o json")
+	if err != nil {
+		return fmt.Errorf("failed to get cluster version: %w", err)
+	}
+	current, err := parseServerVersion(versionOutput)
+	if err != nil {
+		return err
+	}
+	if err := CheckUpgradePath(current, target); err != nil {
+		return err
+	}
+
+	if !sameMinor(current, target) {
+		if err := p.upgradeControlPlane(ctx, cpSSH, current, target); err != nil {
+			return err
+		}
+	}
+
+	nodesOutput, err := cpSSH.Run(ctx, "kubectl get nodes -o json")
+	if err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	nodes, err := parseKubeadmNodes(nodesOutput)
+	if err != nil {
+		return err
+	}
+
+	for i := range order {
+		inst := order[i]
+		node, ok := nodes[inst.PrivateIP]
+		if !ok {
+			return fmt.Errorf("instance %s (%s) is not registered as a node", inst.Name, inst.PrivateIP)
+		}
+		if sameMinor(node.KubeletVersion, target) {
+			continue
+		}
+		if err := p.upgradeNode(ctx, cpSSH, &inst, node.Name, target, i == 0); err != nil {
+			return fmt.Errorf("failed to upgrade %s: %w", inst.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// upgradeControlPlane installs the target kubeadm on the first control plane
+// node and runs kubeadm upgrade apply, which also upgrades CoreDNS and
+// kube-proxy
+func (p *KubeadmProvider) upgradeControlPlane(ctx context.Context, cpSSH *SSHClient, current, target string) error {
+	kubeadmLog.Debugf("upgrading control plane from %s to %s...", current, target)
+
+	if _, err := cpSSH.RunSudoScript(ctx, kubeadmUpgradePackagesScript(target, "kubeadm")); err != nil {
+		return fmt.Errorf("failed to install kubeadm %s: %w", target, err)
+	}
+
+	// kubeadm upgrade apply needs a full version; the package repository
+	// installed the latest patch release of the target minor
+	output, err := cpSSH.Run(ctx, "kubeadm version -o short")
+	if err != nil {
+		return fmt.Errorf("failed to get kubeadm version: %w", err)
+	}
+	version := strings.TrimSpace(output)
+
+	plan, err := cpSSH.RunSudo(ctx, "kubeadm upgrade plan "+version)
+	if err != nil {
+		return fmt.Errorf("kubeadm upgrade plan failed: %w", err)
+	}
+	kubeadmLog.Debugf("upgrade plan:\n%s", plan)
+
+	if _, err := cpSSH.RunSudo(ctx, "kubeadm upgrade apply -y "+version); err != nil {
+		return fmt.Errorf("kubeadm upgrade apply failed: %w", err)
+	}
+	return nil
+}
+
+// upgradeNode upgrades one node's kubelet and kubectl with the node drained.
+// Every node but the first control plane node runs kubeadm upgrade node
+// first to pick up the new control plane and kubelet configuration.
+func (p *KubeadmProvider) upgradeNode(ctx context.Context, cpSSH *SSHClient, inst *Instance, nodeName, target string, primary bool) error {
+	nodeSSH := cpSSH
+	if !primary {
+		var err error
+		nodeSSH, err = p.connect(ctx, inst)
+		if err != nil {
+			return err
+		}
+		defer nodeSSH.Close()
+
+		if _, err := nodeSSH.RunSudoScript(ctx, kubeadmUpgradePackagesScript(target, "kubeadm")); err != nil {
+			return fmt.Errorf("failed to install kubeadm %s: %w", target, err)
+		}
+		if _, err := nodeSSH.RunSudo(ctx, "kubeadm upgrade node"); err != nil {
+			return fmt.Errorf("kubeadm upgrade node failed: %w", err)
+		}
+	}
+
+	kubeadmLog.Debugf("draining %s...", nodeName)
+	if _, err := cpSSH.Run(ctx, fmt.Sprintf("kubectl drain %s --ignore-daemonsets --delete-emptydir-data --timeout=10m", nodeName)); err != nil {
+		return fmt.Errorf("failed to drain node: %w", err)
+	}
+
+	if _, err := nodeSSH.RunSudoScript(ctx, kubeletUpgradeScript(target)); err != nil {
+		// Leave the node cordoned so nothing schedules onto a broken kubelet
+		return fmt.Errorf("failed to upgrade kubelet (node %s left cordoned): %w", nodeName, err)
+	}
+
+	if err := WaitForNodeReady(ctx, cpSSH, DefaultNodeReadyTimeout); err != nil {
+		return fmt.Errorf("node %s did not become ready (left cordoned): %w", nodeName, err)
+	}
+
+	if _, err := cpSSH.Run(ctx, "kubectl uncordon "+nodeName); err != nil {
+		return fmt.Errorf("failed to uncordon node: %w", err)
+	}
+	return nil
+}
+
+// kubeadmUpgradeOrder returns the running nodes in upgrade order: the first
+// control plane node, the other control plane nodes, then the workers. The
+// HA load balancer is not a node and is left out.
+func kubeadmUpgradeOrder(instances []Instance) []Instance {
+	var primary, controlPlanes, workers []Instance
+	for _, inst := range instances {
+		if !inst.Running {
+			continue
+		}
+		switch {
+		case inst.Role == "lb":
+		case inst.Role == "control-plane":
+			primary = append(primary, inst)
+		case strings.HasPrefix(inst.Role, "control-plane"):
+			controlPlanes = append(controlPlanes, inst)
+		default:
+			workers = append(workers, inst)
+		}
+	}
+
+	order := append(primary, controlPlanes...)
+	return append(order, workers...)
+}
+
+// parseServerVersion returns the API server version from
+// `kubectl version -o json`
+func parseServerVersion(output string) (string, error) {
+	var version struct {
+		ServerVersion struct {
+			GitVersion string `json:"gitVersion"`
+		} `json:"serverVersion"`
+	}
+	if err := json.Unmarshal([]byte(output), &version); err != nil {
+		return "", fmt.Errorf("failed to parse cluster version: %w", err)
+	}
+	if version.ServerVersion.GitVersion == "" {
+		return "", fmt.Errorf("cluster did not report a server version")
+	}
+	return version.ServerVersion.GitVersion, nil
+}
+
+// parseKubeadmNodes maps `kubectl get nodes -o json` by node InternalIP,
+// which is the instance private address on every backend
+func parseKubeadmNodes(output string) (map[string]kubeadmNode, error) {
+	var nodeList struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Addresses []struct {
+					Type    string `json:"type"`
+					Address string `json:"address"`
+				} `json:"addresses"`
+				NodeInfo struct {
+					KubeletVersion string `json:"kubeletVersion"`
+				} `json:"nodeInfo"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(output), &nodeList); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	nodes := make(map[string]kubeadmNode, len(nodeList.Items))
+	for _, item := range nodeList.Items {
+		for _, addr := range item.Status.Addresses {
+			if addr.Type == "InternalIP" {
+				nodes[addr.Address] = kubeadmNode{
+					Name:           item.Metadata.Name,
+					KubeletVersion: item.Status.NodeInfo.KubeletVersion,
+				}
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// kubeadmUpgradePackagesScript points apt at the package repository of the
+// target minor version and upgrades packages to its latest patch release
+func kubeadmUpgradePackagesScript(version string, packages ...string) string {
+	return fmt.Sprintf(`set -e
+
+# pkgs.k8s.io has one repository per minor version
+curl -fsSL https://pkgs.k8s.io/core:/stable:/v%[1]s/deb/Release.key | gpg --dearmor --yes -o /etc/apt/keyrings/kubernetes-apt-keyring.gpg
+echo "deb [signed-by=/etc/apt/keyrings/kubernetes-apt-keyring.gpg] https://pkgs.k8s.io/core:/stable:/v%[1]s/deb/ /" | tee /etc/apt/sources.list.d/kubernetes.list
+
+apt-get update
+apt-mark unhold %[2]s
+apt-get install -y %[2]s
+apt-mark hold %[2]s
+`, version, strings.Join(packages, " "))
+}
+
+func kubeletUpgradeScript(version string) string {
+	return kubeadmUpgradePackagesScript(version, "kubelet", "kubectl") + `
+systemctl daemon-reload
+systemctl restart kubelet
+`
+}
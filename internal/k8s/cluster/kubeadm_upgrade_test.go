@@ -0,0 +1,77 @@
+package cluster
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKubeadmUpgradeOrder(t *testing.T) {
+	instances := []Instance{
+		{Name: "c-worker-1", Role: "worker-1", Running: true},
+		{Name: "c-lb", Role: "lb", Running: true},
+		{Name: "c-control-plane-2", Role: "control-plane-2", Running: true},
+		{Name: "c-worker-2", Role: "worker-2", Running: false},
+		{Name: "c-control-plane", Role: "control-plane", Running: true},
+	}
+
+	var names []string
+	for _, inst := range kubeadmUpgradeOrder(instances) {
+		names = append(names, inst.Name)
+	}
+	want := "c-control-plane,c-control-plane-2,c-worker-1"
+	if strings.Join(names, ",") != want {
+		t.Errorf("order = %v, want %s", names, want)
+	}
+}
+
+func TestParseServerVersion(t *testing.T) {
+	version, err := parseServerVersion(`{"clientVersion":{"gitVersion":"v1.30.1"},"serverVersion":{"gitVersion":"v1.29.6"}}`)
+	if err != nil {
+		t.Fatalf("parseServerVersion: %v", err)
+	}
+	if version != "v1.29.6" {
+		t.Errorf("version = %q, want v1.29.6", version)
+	}
+
+	if _, err := parseServerVersion(`{"clientVersion":{"gitVersion":"v1.30.1"}}`); err == nil {
+		t.Error("expected an error without a server version")
+	}
+}
+
+func TestParseKubeadmNodes(t *testing.T) {
+	output := `{"items":[
+		{"metadata":{"name":"ip-10-0-1-5"},"status":{"addresses":[{"type":"InternalIP","address":"10.0.1.5"},{"type":"Hostname","address":"ip-10-0-1-5"}],"nodeInfo":{"kubeletVersion":"v1.29.6"}}},
+		{"metadata":{"name":"c-worker-1"},"status":{"addresses":[{"type":"InternalIP","address":"10.0.1.6"}],"nodeInfo":{"kubeletVersion":"v1.30.2"}}}
+	]}`
+
+	nodes, err := parseKubeadmNodes(output)
+	if err != nil {
+		t.Fatalf("parseKubeadmNodes: %v", err)
+	}
+	if got := nodes["10.0.1.5"]; got.Name != "ip-10-0-1-5" || got.KubeletVersion != "v1.29.6" {
+		t.Errorf("10.0.1.5 = %+v", got)
+	}
+	if got := nodes["10.0.1.6"]; got.Name != "c-worker-1" {
+		t.Errorf("10.0.1.6 = %+v", got)
+	}
+}
+
+func TestKubeadmUpgradeScripts(t *testing.T) {
+	script := kubeadmUpgradePackagesScript("1.30", "kubeadm")
+	for _, want := range []string{
+		"set -e",
+		"https://pkgs.k8s.io/core:/stable:/v1.30/deb/",
+		"apt-mark unhold kubeadm",
+		"apt-get install -y kubeadm",
+		"apt-mark hold kubeadm",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("packages script missing %q:\n%s", want, script)
+		}
+	}
+
+	kubelet := kubeletUpgradeScript("1.30")
+	if !strings.Contains(kubelet, "apt-get install -y kubelet kubectl") || !strings.Contains(kubelet, "systemctl restart kubelet") {
+		t.Errorf("kubelet script:\n%s", kubelet)
+	}
+}
@@ -0,0 +1,48 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultPodRescheduleTimeout is how long a rolling patch waits for the pods
+// evicted from a node to be scheduled again before moving on
+const DefaultPodRescheduleTimeout = 10 * time.Minute
+
+// PatchOptions configures a rolling node OS patch
+type PatchOptions struct {
+	// Progress receives a line as each node or node group starts and
+	// finishes; patching a large cluster takes a long time
+	Progress io.Writer
+}
+
+func (o PatchOptions) progress(provider, format string, args ...interface{}) {
+	if o.Progress == nil {
+		return
+	}
+	fmt.Fprintf(o.Progress, "[%s] %s\n", provider, fmt.Sprintf(format, args...))
+}
+
+// NodePatcher is implemented by providers that can bring node operating
+// systems up to date without taking the cluster down
+type NodePatcher interface {
+	// PatchNodes updates every node one node or node group at a time,
+	// draining each before it is patched or replaced and returning it to
+	// the scheduler afterwards. The Kubernetes version is left unchanged.
+	PatchNodes(ctx context.Context, clusterName string, opts PatchOptions) error
+}
+
+// PatchNodes patches a cluster's nodes with the appropriate provider
+func (m *Manager) PatchNodes(ctx context.Context, clusterType ClusterType, clusterName string, opts PatchOptions) error {
+	provider, ok := m.GetProvider(clusterType)
+	if !ok {
+		return &ErrProviderNotFound{ClusterType: clusterType}
+	}
+	patcher, ok := provider.(NodePatcher)
+	if !ok {
+		return fmt.Errorf("%s clusters do not support node patching", clusterType)
+	}
+	return patcher.PatchNodes(ctx, clusterName, opts)
+}
@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPatchNodesValidation(t *testing.T) {
+	ctx := context.Background()
+	patchers := map[string]NodePatcher{
+		"eks":     NewEKSProvider(EKSProviderOptions{}),
+		"gke":     NewGKEProvider(GKEProviderOptions{}),
+		"aks":     NewAKSProvider(AKSProviderOptions{}),
+		"kubeadm": NewKubeadmProvider(KubeadmProviderOptions{}),
+	}
+	for name, patcher := range patchers {
+		err := patcher.PatchNodes(ctx, "", PatchOptions{})
+		if configErr, ok := err.(*ErrInvalidConfiguration); !ok || configErr.Message != "cluster name is required" {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+
+	if err := NewGKEProvider(GKEProviderOptions{}).PatchNodes(ctx, "prod", PatchOptions{}); err == nil || !strings.Contains(err.Error(), "region is required") {
+		t.Errorf("gke without region: err = %v", err)
+	}
+	if err := NewAKSProvider(AKSProviderOptions{}).PatchNodes(ctx, "prod", PatchOptions{}); err == nil || !strings.Contains(err.Error(), "resource group is required") {
+		t.Errorf("aks without resource group: err = %v", err)
+	}
+}
+
+func TestManagerPatchNodesUnsupported(t *testing.T) {
+	manager := NewManager(false)
+	manager.RegisterProvider(NewExistingProvider("", false))
+
+	err := manager.PatchNodes(context.Background(), ClusterTypeExisting, "local", PatchOptions{})
+	if err == nil || !strings.Contains(err.Error(), "do not support node patching") {
+		t.Errorf("err = %v", err)
+	}
+	if err := manager.PatchNodes(context.Background(), ClusterTypeEKS, "prod", PatchOptions{}); err == nil {
+		t.Error("expected an error for an unregistered provider")
+	}
+}
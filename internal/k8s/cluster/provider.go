@@ -4,6 +4,18 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/log"
+)
+
+// Each provider logs as its own module, so --log can select one: k8s.eks=debug
+var (
+	logger     = log.New("k8s.cluster")
+	aksLog     = log.New("k8s.aks")
+	eksLog     = log.New("k8s.eks")
+	gkeLog     = log.New("k8s.gke")
+	kubeadmLog = log.New("k8s.kubeadm")
+	sshLog     = log.New("k8s.kubeadm.ssh")
 )
 
 // Debug logging format convention:
@@ -56,6 +68,10 @@ const (
 	// DefaultNodeGroupDeleteTimeout is the default timeout for node group deletion
 	DefaultNodeGroupDeleteTimeout = 10 * time.Minute
 
+	// DefaultUpgradeTimeout is the default timeout for a control plane or
+	// node group version upgrade
+	DefaultUpgradeTimeout = 60 * time.Minute
+
 	// DefaultSSHConnectTimeout is the default timeout for SSH connection
 	DefaultSSHConnectTimeout = 5 * time.Minute
 
@@ -117,6 +133,10 @@ type Provider interface {
 	// Scale adjusts cluster node count
 	Scale(ctx context.Context, clusterName string, opts ScaleOptions) error
 
+	// Upgrade moves the control plane and then the nodes to the
+	// Kubernetes minor version targetVersion (e.g. "1.30")
+	Upgrade(ctx context.Context, clusterName, targetVersion string) error
+
 	// GetKubeconfig retrieves cluster credentials
 	GetKubeconfig(ctx context.Context, clusterName string) (string, error)
 
@@ -164,6 +184,15 @@ type CreateOptions struct {
 	GCPNetwork    string
 	GCPSubnetwork string
 	Preemptible   bool
+	// GKEAutopilot creates an Autopilot cluster, where GKE manages the
+	// nodes; worker count and type are ignored
+	GKEAutopilot bool
+	// GKEReleaseChannel enrolls the cluster in rapid, regular, stable or
+	// extended, or in none; empty leaves the GKE default
+	GKEReleaseChannel string
+	// GKEAutoProvisioning turns on node auto-provisioning within its
+	// limits; nil leaves it off
+	GKEAutoProvisioning *GKEAutoProvisioning
 
 	// Azure specific (for AKS)
 	AzureSubscription  string
@@ -251,6 +280,15 @@ func (m *Manager) ScaleCluster(ctx context.Context, clusterType ClusterType, clu
 	return provider.Scale(ctx, clusterName, opts)
 }
 
+// UpgradeCluster upgrades a cluster using the appropriate provider
+func (m *Manager) UpgradeCluster(ctx context.Context, clusterType ClusterType, clusterName, targetVersion string) error {
+	provider, ok := m.GetProvider(clusterType)
+	if !ok {
+		return &ErrProviderNotFound{ClusterType: clusterType}
+	}
+	return provider.Upgrade(ctx, clusterName, targetVersion)
+}
+
 // GetKubeconfig retrieves kubeconfig for a cluster
 func (m *Manager) GetKubeconfig(ctx context.Context, clusterType ClusterType, clusterName string) (string, error) {
 	provider, ok := m.GetProvider(clusterType)
@@ -0,0 +1,117 @@
+package cluster
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// TeardownResource is a cloud resource that belongs to a cluster
+type TeardownResource struct {
+	Type   string `json:"type"` // "node-group", "instance", "security-group", "load-balancer", "volume", ...
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Detail string `json:"detail,omitempty"`
+	// Retained resources are tagged to the cluster but are not removed when
+	// the cluster is deleted
+	Retained bool `json:"retained,omitempty"`
+}
+
+// TeardownReport lists what deleting a cluster would remove and what it
+// would leave behind
+type TeardownReport struct {
+	ClusterName string             `json:"clusterName"`
+	ClusterType ClusterType        `json:"clusterType"`
+	Resources   []TeardownResource `json:"resources"`
+	Warnings    []string           `json:"warnings,omitempty"`
+}
+
+// Removed returns the resources deleted with the cluster
+func (r *TeardownReport) Removed() []TeardownResource {
+	var removed []TeardownResource
+	for _, res := range r.Resources {
+		if !res.Retained {
+			removed = append(removed, res)
+		}
+	}
+	return removed
+}
+
+// Retained returns the resources that outlive the cluster
+func (r *TeardownReport) Retained() []TeardownResource {
+	var retained []TeardownResource
+	for _, res := range r.Resources {
+		if res.Retained {
+			retained = append(retained, res)
+		}
+	}
+	return retained
+}
+
+// TeardownPreviewer is implemented by providers that can list what Delete
+// would remove without deleting anything
+type TeardownPreviewer interface {
+	PreviewDelete(ctx context.Context, clusterName string) (*TeardownReport, error)
+}
+
+// OrphanResource is a resource left behind by a deleted cluster or a failed
+// creation
+type OrphanResource struct {
+	Type    string `json:"type"`
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Cluster string `json:"cluster"`
+	Reason  string `json:"reason"`
+}
+
+// OrphanSweeper is implemented by providers that can find and delete
+// orphaned resources
+type OrphanSweeper interface {
+	// FindOrphans lists orphaned resources without deleting anything
+	FindOrphans(ctx context.Context) ([]OrphanResource, error)
+	// DeleteOrphans deletes the given orphans, in dependency order
+	DeleteOrphans(ctx context.Context, orphans []OrphanResource) error
+}
+
+// orphanDeleteOrder ranks resource types so that resources are deleted
+// before the ones they depend on (load balancers hold ENIs, instances and
+// ENIs hold security groups)
+var orphanDeleteOrder = map[string]int{
+	"load-balancer":     0,
+	"target-group":      1,
+	"instance":          2,
+	"network-interface": 3,
+	"volume":            4,
+	"security-group":    5,
+	"firewall":          5,
+	"network":           6,
+}
+
+// sortOrphansForDeletion orders orphans by orphanDeleteOrder, keeping the
+// input order within a type
+func sortOrphansForDeletion(orphans []OrphanResource) []OrphanResource {
+	sorted := append([]OrphanResource(nil), orphans...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return orphanDeleteOrder[sorted[i].Type] < orphanDeleteOrder[sorted[j].Type]
+	})
+	return sorted
+}
+
+// clusterTagPrefix is the tag key prefix Kubernetes, EKS and kubeadm on EC2
+// put on cluster resources: kubernetes.io/cluster/<name>=owned|shared
+const clusterTagPrefix = "kubernetes.io/cluster/"
+
+// taggedClusterName returns the cluster a resource is tagged to, or ""
+func taggedClusterName(tags map[string]string) string {
+	for _, key := range []string{"aws:eks:cluster-name", "eks:cluster-name", "elbv2.k8s.aws/cluster", "cluster.k8s.amazonaws.com/name"} {
+		if name := tags[key]; name != "" {
+			return name
+		}
+	}
+	for key := range tags {
+		if name, ok := strings.CutPrefix(key, clusterTagPrefix); ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,294 @@
+package cluster
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const taggedResourcesJSON = `{"ResourceTagMappingList":[
+	{"ResourceARN":"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/net/k8s-web-abc/0123456789abcdef",
+	 "Tags":[{"Key":"elbv2.k8s.aws/cluster","Value":"gone"},{"Key":"kubernetes.io/service-name","Value":"shop/web"}]},
+	{"ResourceARN":"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/a1b2c3",
+	 "Tags":[{"Key":"kubernetes.io/cluster/prod","Value":"owned"}]},
+	{"ResourceARN":"arn:aws:ec2:us-east-1:123456789012:volume/vol-0abc",
+	 "Tags":[{"Key":"kubernetes.io/cluster/gone","Value":"owned"},
+	         {"Key":"kubernetes.io/created-for/pvc/namespace","Value":"db"},
+	         {"Key":"kubernetes.io/created-for/pvc/name","Value":"data-pg-0"}]},
+	{"ResourceARN":"arn:aws:ec2:us-east-1:123456789012:security-group/sg-0eks",
+	 "Tags":[{"Key":"aws:eks:cluster-name","Value":"prod"},{"Key":"kubernetes.io/cluster/prod","Value":"owned"}]},
+	{"ResourceARN":"arn:aws:ec2:us-east-1:123456789012:security-group/sg-0shared",
+	 "Tags":[{"Key":"kubernetes.io/cluster/gone","Value":"shared"}]},
+	{"ResourceARN":"arn:aws:eks:us-east-1:123456789012:nodegroup/prod/ng-1/abc",
+	 "Tags":[{"Key":"kubernetes.io/cluster/gone","Value":"owned"}]}
+]}`
+
+func TestClassifyEKSResource(t *testing.T) {
+	tagged, err := parseTaggedResources(taggedResourcesJSON)
+	if err != nil {
+		t.Fatalf("parseTaggedResources: %v", err)
+	}
+	if len(tagged) != 6 {
+		t.Fatalf("parsed %d resources, want 6", len(tagged))
+	}
+
+	tests := []struct {
+		index    int
+		typ      string
+		name     string
+		retained bool
+		detail   string
+	}{
+		{0, "load-balancer", "k8s-web-abc", true, "Service shop/web"},
+		{1, "load-balancer", "a1b2c3", true, ""},
+		{2, "volume", "", true, "PVC db/data-pg-0"},
+		{3, "security-group", "", false, "EKS cluster security group"},
+		{4, "security-group", "", true, "created for a LoadBalancer Service"},
+	}
+	for _, tt := range tests {
+		res, ok := classifyEKSResource(tagged[tt.index])
+		if !ok {
+			t.Errorf("resource %d skipped", tt.index)
+			continue
+		}
+		if res.Type != tt.typ || res.Name != tt.name || res.Retained != tt.retained || res.Detail != tt.detail {
+			t.Errorf("resource %d = %+v", tt.index, res)
+		}
+	}
+
+	if _, ok := classifyEKSResource(tagged[5]); ok {
+		t.Error("EKS node groups should be skipped")
+	}
+}
+
+func TestFindEKSOrphans(t *testing.T) {
+	tagged, err := parseTaggedResources(taggedResourcesJSON)
+	if err != nil {
+		t.Fatalf("parseTaggedResources: %v", err)
+	}
+
+	orphans := findEKSOrphans(tagged, map[string]bool{"prod": true})
+	var got []string
+	for _, o := range orphans {
+		got = append(got, o.Cluster+"/"+o.Type)
+	}
+	// The shared security group and the node group are not orphans
+	want := "gone/load-balancer,gone/volume"
+	if strings.Join(got, ",") != want {
+		t.Errorf("orphans = %v, want %s", got, want)
+	}
+	if !strings.Contains(orphans[1].Reason, "PVC db/data-pg-0") {
+		t.Errorf("volume reason = %q", orphans[1].Reason)
+	}
+}
+
+func TestEKSOrphanDeleteArgs(t *testing.T) {
+	tests := []struct {
+		orphan OrphanResource
+		want   string
+	}{
+		{OrphanResource{Type: "load-balancer", ID: "arn:aws:elasticloadbalancing:us-east-1:1:loadbalancer/net/web/abc", Name: "web"},
+			"elbv2 delete-load-balancer --load-balancer-arn arn:aws:elasticloadbalancing:us-east-1:1:loadbalancer/net/web/abc"},
+		{OrphanResource{Type: "load-balancer", ID: "arn:aws:elasticloadbalancing:us-east-1:1:loadbalancer/a1b2c3", Name: "a1b2c3"},
+			"elb delete-load-balancer --load-balancer-name a1b2c3"},
+		{OrphanResource{Type: "volume", ID: "vol-1"}, "ec2 delete-volume --volume-id vol-1"},
+		{OrphanResource{Type: "network-interface", ID: "eni-1"}, "ec2 delete-network-interface --network-interface-id eni-1"},
+		{OrphanResource{Type: "security-group", ID: "sg-1"}, "ec2 delete-security-group --group-id sg-1"},
+	}
+	for _, tt := range tests {
+		args, err := eksOrphanDeleteArgs(tt.orphan)
+		if err != nil {
+			t.Errorf("%s: %v", tt.orphan.Type, err)
+			continue
+		}
+		if got := strings.Join(args, " "); got != tt.want {
+			t.Errorf("%s: args = %s, want %s", tt.orphan.Type, got, tt.want)
+		}
+	}
+
+	if _, err := eksOrphanDeleteArgs(OrphanResource{Type: "bucket", ID: "b"}); err == nil {
+		t.Error("unsupported types should fail")
+	}
+}
+
+func TestSortOrphansForDeletion(t *testing.T) {
+	sorted := sortOrphansForDeletion([]OrphanResource{
+		{Type: "security-group", ID: "sg-1"},
+		{Type: "volume", ID: "vol-1"},
+		{Type: "load-balancer", ID: "lb-1"},
+		{Type: "network-interface", ID: "eni-1"},
+	})
+	var ids []string
+	for _, o := range sorted {
+		ids = append(ids, o.ID)
+	}
+	if strings.Join(ids, ",") != "lb-1,eni-1,vol-1,sg-1" {
+		t.Errorf("order = %v", ids)
+	}
+}
+
+func TestParseNetworkInterfaces(t *testing.T) {
+	enis, err := parseNetworkInterfaces(`{"NetworkInterfaces":[
+		{"NetworkInterfaceId":"eni-1","Status":"available","Description":"aws-K8S-i-0abc",
+		 "TagSet":[{"Key":"cluster.k8s.amazonaws.com/name","Value":"gone"}]}]}`)
+	if err != nil {
+		t.Fatalf("parseNetworkInterfaces: %v", err)
+	}
+	if len(enis) != 1 || enis[0].ID != "eni-1" || enis[0].Status != "available" || taggedClusterName(enis[0].Tags) != "gone" {
+		t.Errorf("enis = %+v", enis)
+	}
+}
+
+func TestParseEC2ClusterNames(t *testing.T) {
+	names, err := parseEC2ClusterNames(`["Name","kubernetes.io/cluster/lab","kubernetes.io/cluster/demo","kubernetes.io/cluster/lab"]`)
+	if err != nil {
+		t.Fatalf("parseEC2ClusterNames: %v", err)
+	}
+	if strings.Join(names, ",") != "demo,lab" {
+		t.Errorf("names = %v", names)
+	}
+}
+
+// sweepBackend serves instances and networks per cluster and records
+// deletions
+type sweepBackend struct {
+	ec2Backend
+	instances  map[string][]Instance
+	networks   map[string]string
+	terminated []string
+	cleaned    []string
+}
+
+func (b *sweepBackend) ListClusters(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	for name := range b.instances {
+		seen[name] = true
+	}
+	for name := range b.networks {
+		seen[name] = true
+	}
+	var names []string
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (b *sweepBackend) ListInstances(ctx context.Context, clusterName string) ([]Instance, error) {
+	return b.instances[clusterName], nil
+}
+
+func (b *sweepBackend) FindCluster(ctx context.Context, clusterName string) (string, error) {
+	if network, ok := b.networks[clusterName]; ok {
+		return network, nil
+	}
+	return "", &ErrClusterNotFound{ClusterName: clusterName}
+}
+
+func (b *sweepBackend) Terminate(ctx context.Context, instanceID string) error {
+	b.terminated = append(b.terminated, instanceID)
+	return nil
+}
+
+func (b *sweepBackend) CleanupCluster(ctx context.Context, clusterName string) error {
+	b.cleaned = append(b.cleaned, clusterName)
+	return nil
+}
+
+func TestKubeadmFindOrphans(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	backend := &sweepBackend{
+		instances: map[string][]Instance{
+			"healthy":  {{ID: "i-1", Role: "control-plane"}, {ID: "i-2", Role: "worker-0"}},
+			"headless": {{ID: "i-3", Role: "worker-0"}},
+			"failed":   {{ID: "i-4", Role: "control-plane"}},
+		},
+		networks: map[string]string{
+			"healthy":  "sg-healthy",
+			"headless": "sg-headless",
+			"failed":   "sg-failed",
+			"empty":    "sg-empty",
+		},
+	}
+	if err := SaveKubeadmCreateState(&KubeadmCreateState{
+		ClusterName: "failed",
+		Backend:     "ec2",
+		Step:        KubeadmStepControlPlaneLaunched,
+		LastError:   "ssh: connection refused",
+	}); err != nil {
+		t.Fatalf("SaveKubeadmCreateState: %v", err)
+	}
+
+	provider := NewKubeadmProvider(KubeadmProviderOptions{Backend: backend})
+	orphans, err := provider.FindOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("FindOrphans: %v", err)
+	}
+
+	var got []string
+	for _, o := range orphans {
+		got = append(got, o.Cluster+"/"+o.ID)
+	}
+	want := "empty/sg-empty,failed/i-4,failed/sg-failed,headless/i-3,headless/sg-headless"
+	if strings.Join(got, ",") != want {
+		t.Errorf("orphans = %v, want %s", got, want)
+	}
+	if !strings.Contains(orphans[1].Reason, "--resume") {
+		t.Errorf("failed creation reason = %q", orphans[1].Reason)
+	}
+
+	// Selecting only instances leaves the network in place
+	if err := provider.DeleteOrphans(context.Background(), orphans[1:2]); err != nil {
+		t.Fatalf("DeleteOrphans: %v", err)
+	}
+	if strings.Join(backend.terminated, ",") != "i-4" || len(backend.cleaned) != 0 {
+		t.Errorf("terminated = %v, cleaned = %v", backend.terminated, backend.cleaned)
+	}
+
+	// A network alone is cleaned up without waiting, and the state goes with it
+	if err := provider.DeleteOrphans(context.Background(), orphans[2:3]); err != nil {
+		t.Fatalf("DeleteOrphans: %v", err)
+	}
+	if strings.Join(backend.cleaned, ",") != "failed" {
+		t.Errorf("cleaned = %v", backend.cleaned)
+	}
+	if state, _ := LoadKubeadmCreateState("failed"); state != nil {
+		t.Error("creation state should be removed with the network")
+	}
+}
+
+func TestKubeadmPreviewDelete(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	backend := &sweepBackend{
+		instances: map[string][]Instance{
+			"demo": {{ID: "i-1", Name: "demo-control-plane", Role: "control-plane", PublicIP: "203.0.113.10"}},
+		},
+		networks: map[string]string{"demo": "sg-demo"},
+	}
+	provider := NewKubeadmProvider(KubeadmProviderOptions{Backend: backend})
+
+	report, err := provider.PreviewDelete(context.Background(), "demo")
+	if err != nil {
+		t.Fatalf("PreviewDelete: %v", err)
+	}
+	if len(report.Removed()) != 2 || len(report.Retained()) != 0 {
+		t.Fatalf("resources = %+v", report.Resources)
+	}
+	if sg := report.Resources[1]; sg.Type != "security-group" || sg.Name != "demo-k8s-sg" {
+		t.Errorf("security group = %+v", sg)
+	}
+	if report.Resources[0].Detail != "control-plane, 203.0.113.10" {
+		t.Errorf("instance detail = %q", report.Resources[0].Detail)
+	}
+
+	if _, err := provider.PreviewDelete(context.Background(), "missing"); err == nil {
+		t.Error("PreviewDelete of an unknown cluster should fail")
+	}
+	if len(backend.terminated) != 0 || len(backend.cleaned) != 0 {
+		t.Error("PreviewDelete must not delete anything")
+	}
+}
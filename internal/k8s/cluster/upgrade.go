@@ -0,0 +1,83 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseKubernetesMinor returns the major and minor parts of a version such
+// as "1.30", "v1.30.2" or "1.30.2-eks-1234"
+func ParseKubernetesMinor(version string) (int, int, error) {
+	v := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("invalid Kubernetes version %q", version)
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Kubernetes version %q", version)
+	}
+
+	// The minor part may carry a provider suffix ("30+" on EKS server versions)
+	minorPart := parts[1]
+	if i := strings.IndexFunc(minorPart, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minorPart = minorPart[:i]
+	}
+	minor, err := strconv.Atoi(minorPart)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid Kubernetes version %q", version)
+	}
+
+	return major, minor, nil
+}
+
+// KubernetesMinor normalizes a version to "<major>.<minor>"
+func KubernetesMinor(version string) (string, error) {
+	major, minor, err := ParseKubernetesMinor(version)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d", major, minor), nil
+}
+
+// CheckUpgradePath verifies target is reachable from current in one step.
+// Kubernetes only supports upgrading the control plane one minor version at
+// a time and never downgrading. Upgrading to the current minor is allowed so
+// an interrupted upgrade can finish the nodes.
+func CheckUpgradePath(current, target string) error {
+	curMajor, curMinor, err := ParseKubernetesMinor(current)
+	if err != nil {
+		return err
+	}
+	tgtMajor, tgtMinor, err := ParseKubernetesMinor(target)
+	if err != nil {
+		return &ErrInvalidConfiguration{Message: err.Error()}
+	}
+
+	if tgtMajor != curMajor {
+		return &ErrInvalidConfiguration{Message: fmt.Sprintf("cannot upgrade from %s to %s: major version upgrades are not supported", current, target)}
+	}
+	if tgtMinor < curMinor {
+		return &ErrInvalidConfiguration{Message: fmt.Sprintf("cannot downgrade from %d.%d to %d.%d", curMajor, curMinor, tgtMajor, tgtMinor)}
+	}
+	if tgtMinor > curMinor+1 {
+		return &ErrInvalidConfiguration{Message: fmt.Sprintf("cannot upgrade from %d.%d to %d.%d in one step; upgrade to %d.%d first",
+			curMajor, curMinor, tgtMajor, tgtMinor, curMajor, curMinor+1)}
+	}
+	return nil
+}
+
+// sameMinor reports whether two versions share a major and minor version
+func sameMinor(a, b string) bool {
+	aMajor, aMinor, err := ParseKubernetesMinor(a)
+	if err != nil {
+		return false
+	}
+	bMajor, bMinor, err := ParseKubernetesMinor(b)
+	if err != nil {
+		return false
+	}
+	return aMajor == bMajor && aMinor == bMinor
+}
@@ -0,0 +1,61 @@
+package cluster
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseKubernetesMinor(t *testing.T) {
+	tests := []struct {
+		version string
+		major   int
+		minor   int
+		wantErr bool
+	}{
+		{version: "1.30", major: 1, minor: 30},
+		{version: "v1.29.4", major: 1, minor: 29},
+		{version: "1.28.5-eks-5e0fdde", major: 1, minor: 28},
+		{version: "1.31.1-gke.1146000", major: 1, minor: 31},
+		{version: "v1.30+", major: 1, minor: 30},
+		{version: "1", wantErr: true},
+		{version: "latest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		major, minor, err := ParseKubernetesMinor(tt.version)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseKubernetesMinor(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (major != tt.major || minor != tt.minor) {
+			t.Errorf("ParseKubernetesMinor(%q) = %d.%d, want %d.%d", tt.version, major, minor, tt.major, tt.minor)
+		}
+	}
+}
+
+func TestCheckUpgradePath(t *testing.T) {
+	tests := []struct {
+		current string
+		target  string
+		wantErr bool
+	}{
+		{current: "1.29", target: "1.30"},
+		{current: "v1.29.4", target: "1.30"},
+		{current: "1.30.2", target: "1.30"}, // finishing the nodes
+		{current: "1.28", target: "1.30", wantErr: true},
+		{current: "1.30", target: "1.29", wantErr: true},
+		{current: "1.30", target: "2.0", wantErr: true},
+		{current: "1.30", target: "next", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := CheckUpgradePath(tt.current, tt.target)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckUpgradePath(%q, %q) error = %v, wantErr %v", tt.current, tt.target, err, tt.wantErr)
+		}
+		var invalid *ErrInvalidConfiguration
+		if err != nil && !errors.As(err, &invalid) {
+			t.Errorf("CheckUpgradePath(%q, %q) error is %T, want *ErrInvalidConfiguration", tt.current, tt.target, err)
+		}
+	}
+}
@@ -182,6 +182,12 @@ func (p *VerdaInstantProvider) Scale(ctx context.Context, clusterName string, op
 	return fmt.Errorf("scale not supported for verda-instant — pick a larger cluster_type and recreate")
 }
 
+// Upgrade is not supported — Verda manages the Kubernetes version of
+// Instant Clusters.
+func (p *VerdaInstantProvider) Upgrade(ctx context.Context, clusterName, targetVersion string) error {
+	return fmt.Errorf("upgrade not supported for verda-instant — recreate the cluster to change versions")
+}
+
 // GetKubeconfig SCPs `/root/.kube/config` off the first worker node and
 // rewrites the `server:` URL to the node's public IP so kubectl can reach it
 // from outside Verda's private network.
@@ -0,0 +1,322 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ContextAction is a kubeconfig context operation requested in natural language
+type ContextAction string
+
+const (
+	ContextActionSwitch  ContextAction = "switch"
+	ContextActionCompare ContextAction = "compare"
+)
+
+// ContextIntent is the parsed form of a context switch or comparison request
+type ContextIntent struct {
+	Action       ContextAction
+	Contexts     []string
+	ResourceType string
+	Name         string
+	Namespace    string
+}
+
+const contextNamePattern = `([\w.@:/-]+)`
+
+var (
+	contextSwitchPattern  = regexp.MustCompile(`(?i)\b(?:switch|change|use)\s+(?:to\s+)?(?:the\s+)?(?:kube(?:ctl)?\s+)?context\s+(?:to\s+)?` + contextNamePattern)
+	contextComparePattern = regexp.MustCompile(`(?i)\b(?:compare|diff)\s+(?:the\s+)?([a-z][\w.]*)\s+([\w.-]+)\s+(?:between|across|in)\s+(?:the\s+)?(?:kube(?:ctl)?\s+)?contexts?\s+` +
+		contextNamePattern + `\s+(?:and|vs\.?|versus|with)\s+` + contextNamePattern)
+	contextNamespacePattern = regexp.MustCompile(`(?i)(?:\bin\s+(?:the\s+)?namespace\s+|\bnamespace\s+|-n\s+)([a-z0-9][a-z0-9-]*)`)
+)
+
+// ParseContextQuery recognises "switch to context staging" and "compare
+// deployment foo between contexts prod and staging".
+func ParseContextQuery(query string) (ContextIntent, bool) {
+	query = strings.TrimSpace(query)
+
+	if m := contextComparePattern.FindStringSubmatch(query); m != nil {
+		intent := ContextIntent{
+			Action:       ContextActionCompare,
+			ResourceType: strings.ToLower(m[1]),
+			Name:         m[2],
+			Contexts:     []string{trimContextName(m[3]), trimContextName(m[4])},
+		}
+		if ns := contextNamespacePattern.FindStringSubmatch(query); ns != nil {
+			intent.Namespace = ns[1]
+		}
+		return intent, true
+	}
+
+	if m := contextSwitchPattern.FindStringSubmatch(query); m != nil {
+		if name := trimContextName(m[1]); name != "" {
+			return ContextIntent{Action: ContextActionSwitch, Contexts: []string{name}}, true
+		}
+	}
+	return ContextIntent{}, false
+}
+
+// ExecuteContextIntent switches the client (and kubeconfig) to a new context
+// or runs a cross-context comparison, returning a printable result.
+func ExecuteContextIntent(ctx context.Context, client *Client, intent ContextIntent) (string, error) {
+	switch intent.Action {
+	case ContextActionSwitch:
+		target := intent.Contexts[0]
+		available, err := client.GetContexts(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to list contexts: %w", err)
+		}
+		if !containsString(available, target) {
+			return "", fmt.Errorf("context %q not found in kubeconfig (available: %s)", target, strings.Join(available, ", "))
+		}
+		if err := client.UseContext(ctx, target); err != nil {
+			return "", fmt.Errorf("failed to switch context: %w", err)
+		}
+		client.SetContext(target)
+		return fmt.Sprintf("Switched to context %s\n", target), nil
+
+	case ContextActionCompare:
+		cmp, err := client.CompareAcrossContexts(ctx, intent.ResourceType, intent.Name, intent.Namespace, intent.Contexts)
+		if err != nil {
+			return "", err
+		}
+		return FormatContextComparison(cmp), nil
+	}
+	return "", fmt.Errorf("unsupported context action %q", intent.Action)
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+func trimContextName(name string) string {
+	return strings.TrimRight(strings.TrimSpace(name), ".,;:!?")
+}
+
+// ContextSnapshot is one context's view of the compared object
+type ContextSnapshot struct {
+	Context string            `json:"context"`
+	Missing bool              `json:"missing,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// ContextFieldDiff is a field whose value differs between contexts
+type ContextFieldDiff struct {
+	Field  string            `json:"field"`
+	Values map[string]string `json:"values"`
+}
+
+// ContextComparison is the result of fetching one object from several contexts
+type ContextComparison struct {
+	ResourceType string             `json:"resourceType"`
+	Name         string             `json:"name"`
+	Namespace    string             `json:"namespace,omitempty"`
+	Contexts     []string           `json:"contexts"`
+	Snapshots    []ContextSnapshot  `json:"snapshots"`
+	Diffs        []ContextFieldDiff `json:"diffs,omitempty"`
+}
+
+// CompareAcrossContexts fetches the same object from each context in
+// parallel and diffs spec, labels, and readiness fields.
+func (c *Client) CompareAcrossContexts(ctx context.Context, resourceType, name, namespace string, contexts []string) (*ContextComparison, error) {
+	if len(contexts) < 2 {
+		return nil, fmt.Errorf("need at least two contexts to compare")
+	}
+
+	objects := make(map[string][]byte, len(contexts))
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, kubeContext := range contexts {
+		wg.Add(1)
+		go func(kubeContext string) {
+			defer wg.Done()
+			client := NewClient(c.kubeconfig, kubeContext, c.debug)
+			client.SetBackend(c.backend)
+			client.SetNamespace(c.namespace)
+			data, err := client.GetJSON(ctx, resourceType, name, namespace)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[kubeContext] = err
+				return
+			}
+			objects[kubeContext] = data
+		}(kubeContext)
+	}
+	wg.Wait()
+
+	return BuildContextComparison(resourceType, name, namespace, contexts, objects, errs), nil
+}
+
+// BuildContextComparison flattens each context's object and reports the
+// fields that differ. Fetch errors mentioning NotFound mark the object as
+// missing in that context.
+func BuildContextComparison(resourceType, name, namespace string, contexts []string, objects map[string][]byte, errs map[string]error) *ContextComparison {
+	cmp := &ContextComparison{
+		ResourceType: resourceType,
+		Name:         name,
+		Namespace:    namespace,
+		Contexts:     contexts,
+	}
+
+	present := make([]ContextSnapshot, 0, len(contexts))
+	for _, kubeContext := range contexts {
+		snap := ContextSnapshot{Context: kubeContext}
+		if err, ok := errs[kubeContext]; ok {
+			msg := err.Error()
+			if strings.Contains(msg, "NotFound") || strings.Contains(msg, "not found") {
+				snap.Missing = true
+			} else {
+				snap.Error = msg
+			}
+			cmp.Snapshots = append(cmp.Snapshots, snap)
+			continue
+		}
+
+		var obj map[string]interface{}
+		if err := json.Unmarshal(objects[kubeContext], &obj); err != nil {
+			snap.Error = fmt.Sprintf("parse object: %v", err)
+			cmp.Snapshots = append(cmp.Snapshots, snap)
+			continue
+		}
+		snap.Fields = comparableFields(obj)
+		cmp.Snapshots = append(cmp.Snapshots, snap)
+		present = append(present, snap)
+	}
+
+	keys := map[string]struct{}{}
+	for _, snap := range present {
+		for k := range snap.Fields {
+			keys[k] = struct{}{}
+		}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	for _, field := range sorted {
+		values := make(map[string]string, len(present))
+		distinct := map[string]struct{}{}
+		for _, snap := range present {
+			v, ok := snap.Fields[field]
+			if !ok {
+				v = "<unset>"
+			}
+			values[snap.Context] = v
+			distinct[v] = struct{}{}
+		}
+		if len(distinct) > 1 {
+			cmp.Diffs = append(cmp.Diffs, ContextFieldDiff{Field: field, Values: values})
+		}
+	}
+	return cmp
+}
+
+// comparableFields flattens the parts of an object worth comparing between
+// environments. Server-managed metadata and most of status are skipped.
+func comparableFields(obj map[string]interface{}) map[string]string {
+	out := map[string]string{}
+	if spec, ok := obj["spec"]; ok {
+		flattenContextValue("spec", spec, out)
+	}
+	if data, ok := obj["data"]; ok {
+		flattenContextValue("data", data, out)
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		if labels, ok := metadata["labels"]; ok {
+			flattenContextValue("metadata.labels", labels, out)
+		}
+	}
+	if status, ok := obj["status"].(map[string]interface{}); ok {
+		for _, key := range []string{"replicas", "readyReplicas", "availableReplicas", "phase"} {
+			if v, ok := status[key]; ok {
+				flattenContextValue("status."+key, v, out)
+			}
+		}
+	}
+	return out
+}
+
+// flattenContextValue writes dotted paths; lists of named items (containers,
+// env, ports) are keyed by name so reordering does not show up as a diff.
+func flattenContextValue(prefix string, value interface{}, out map[string]string) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for k, v := range typed {
+			flattenContextValue(prefix+"."+k, v, out)
+		}
+	case []interface{}:
+		for i, item := range typed {
+			key := fmt.Sprintf("%d", i)
+			if m, ok := item.(map[string]interface{}); ok {
+				if name, ok := m["name"].(string); ok && name != "" {
+					key = name
+				}
+			}
+			flattenContextValue(fmt.Sprintf("%s[%s]", prefix, key), item, out)
+		}
+	case nil:
+		out[prefix] = ""
+	case string:
+		out[prefix] = typed
+	case float64:
+		if typed == float64(int64(typed)) {
+			out[prefix] = fmt.Sprintf("%d", int64(typed))
+		} else {
+			out[prefix] = fmt.Sprintf("%g", typed)
+		}
+	default:
+		out[prefix] = fmt.Sprint(typed)
+	}
+}
+
+// FormatContextComparison renders a comparison as a plain-text report
+func FormatContextComparison(cmp *ContextComparison) string {
+	var sb strings.Builder
+	target := fmt.Sprintf("%s/%s", cmp.ResourceType, cmp.Name)
+	if cmp.Namespace != "" {
+		target += fmt.Sprintf(" (namespace %s)", cmp.Namespace)
+	}
+	sb.WriteString(fmt.Sprintf("Comparing %s across contexts: %s\n\n", target, strings.Join(cmp.Contexts, ", ")))
+
+	for _, snap := range cmp.Snapshots {
+		switch {
+		case snap.Missing:
+			sb.WriteString(fmt.Sprintf("  %s: not found\n", snap.Context))
+		case snap.Error != "":
+			sb.WriteString(fmt.Sprintf("  %s: error: %s\n", snap.Context, snap.Error))
+		}
+	}
+
+	if len(cmp.Diffs) == 0 {
+		sb.WriteString("No differences found.\n")
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("%d differing field(s):\n", len(cmp.Diffs)))
+	for _, diff := range cmp.Diffs {
+		sb.WriteString(fmt.Sprintf("\n  %s\n", diff.Field))
+		for _, kubeContext := range cmp.Contexts {
+			if v, ok := diff.Values[kubeContext]; ok {
+				sb.WriteString(fmt.Sprintf("    %-20s %s\n", kubeContext+":", v))
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,101 @@
+package k8s
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseContextQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		ok    bool
+		want  ContextIntent
+	}{
+		{query: "switch to context staging", ok: true, want: ContextIntent{Action: ContextActionSwitch, Contexts: []string{"staging"}}},
+		{query: "switch context to arn:aws:eks:us-east-1:123:cluster/prod.", ok: true, want: ContextIntent{Action: ContextActionSwitch, Contexts: []string{"arn:aws:eks:us-east-1:123:cluster/prod"}}},
+		{query: "use kube context dev", ok: true, want: ContextIntent{Action: ContextActionSwitch, Contexts: []string{"dev"}}},
+		{
+			query: "compare deployment foo between contexts prod and staging",
+			ok:    true,
+			want:  ContextIntent{Action: ContextActionCompare, ResourceType: "deployment", Name: "foo", Contexts: []string{"prod", "staging"}},
+		},
+		{
+			query: "diff configmap app-config across contexts prod vs staging in namespace web",
+			ok:    true,
+			want:  ContextIntent{Action: ContextActionCompare, ResourceType: "configmap", Name: "app-config", Contexts: []string{"prod", "staging"}, Namespace: "web"},
+		},
+		{query: "list pods in namespace foo", ok: false},
+		{query: "what context am I using", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseContextQuery(tt.query)
+		if ok != tt.ok {
+			t.Errorf("ParseContextQuery(%q) ok = %v, want %v", tt.query, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if got.Action != tt.want.Action || got.ResourceType != tt.want.ResourceType || got.Name != tt.want.Name ||
+			got.Namespace != tt.want.Namespace || strings.Join(got.Contexts, ",") != strings.Join(tt.want.Contexts, ",") {
+			t.Errorf("ParseContextQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestBuildContextComparison(t *testing.T) {
+	prod := []byte(`{
+		"metadata": {"name": "foo", "uid": "a", "resourceVersion": "1", "labels": {"app": "foo"}},
+		"spec": {"replicas": 3, "template": {"spec": {"containers": [
+			{"name": "app", "image": "foo:1.2", "env": [{"name": "MODE", "value": "prod"}]},
+			{"name": "sidecar", "image": "proxy:1"}
+		]}}},
+		"status": {"readyReplicas": 3, "observedGeneration": 7}
+	}`)
+	staging := []byte(`{
+		"metadata": {"name": "foo", "uid": "b", "resourceVersion": "9", "labels": {"app": "foo"}},
+		"spec": {"replicas": 1, "template": {"spec": {"containers": [
+			{"name": "sidecar", "image": "proxy:1"},
+			{"name": "app", "image": "foo:1.3", "env": [{"name": "MODE", "value": "prod"}]}
+		]}}},
+		"status": {"readyReplicas": 1, "observedGeneration": 2}
+	}`)
+
+	cmp := BuildContextComparison("deployment", "foo", "web",
+		[]string{"prod", "staging", "dev"},
+		map[string][]byte{"prod": prod, "staging": staging},
+		map[string]error{"dev": errors.New(`Error from server (NotFound): deployments.apps "foo" not found`)},
+	)
+
+	got := map[string]ContextFieldDiff{}
+	for _, d := range cmp.Diffs {
+		got[d.Field] = d
+	}
+	for _, field := range []string{
+		"spec.replicas",
+		"spec.template.spec.containers[app].image",
+		"status.readyReplicas",
+	} {
+		if _, ok := got[field]; !ok {
+			t.Errorf("expected diff on %s, got %+v", field, cmp.Diffs)
+		}
+	}
+	if len(cmp.Diffs) != 3 {
+		t.Errorf("expected 3 diffs (uid, resourceVersion, and container order ignored), got %d: %+v", len(cmp.Diffs), cmp.Diffs)
+	}
+	if d := got["spec.replicas"]; d.Values["prod"] != "3" || d.Values["staging"] != "1" {
+		t.Errorf("replica values = %+v", d.Values)
+	}
+	if !cmp.Snapshots[2].Missing {
+		t.Errorf("dev snapshot should be missing: %+v", cmp.Snapshots[2])
+	}
+
+	report := FormatContextComparison(cmp)
+	for _, want := range []string{"dev: not found", "3 differing field(s)", "foo:1.3"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
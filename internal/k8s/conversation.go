@@ -2,16 +2,15 @@ package k8s
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/bgdnvk/clanker/internal/convhistory"
+	"github.com/bgdnvk/clanker/internal/k8s/workloads"
 )
 
 // ConversationEntry represents a single Q&A exchange
@@ -85,15 +84,7 @@ func (h *ConversationHistory) AddEntry(question, answer, cluster string) {
 		Cluster:   cluster,
 	}
 
-	h.Entries = append(h.Entries, entry)
-
-	// Trim old entries to keep history manageable. The limit is resolved
-	// on every append (via env) so long-running processes pick up
-	// CLANKER_K8S_HISTORY_MAX changes without restart.
-	limit := resolveMaxHistoryEntries()
-	if len(h.Entries) > limit {
-		h.Entries = h.Entries[len(h.Entries)-limit:]
-	}
+	h.Entries = convhistory.Append(h.Entries, entry, resolveMaxHistoryEntries())
 }
 
 // GetRecentContext returns recent conversation context as a formatted string
@@ -117,7 +108,7 @@ func (h *ConversationHistory) GetRecentContext(maxEntries int) string {
 			sb.WriteString("\n")
 		}
 		sb.WriteString(fmt.Sprintf("Q: %s\n", entry.Question))
-		sb.WriteString(fmt.Sprintf("A: %s\n", truncateText(entry.Answer, MaxAnswerLengthInContext)))
+		sb.WriteString(fmt.Sprintf("A: %s\n", convhistory.Truncate(entry.Answer, MaxAnswerLengthInContext)))
 	}
 
 	return sb.String()
@@ -169,86 +160,20 @@ func (h *ConversationHistory) Clear() {
 	h.LastStatus = nil
 }
 
-// Save persists the conversation history to disk
+// Save persists the conversation history to ~/.clanker/conversations/k8s_<clustername>.json
 func (h *ConversationHistory) Save() error {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-
-	dir, err := getConversationDir()
-	if err != nil {
-		return err
-	}
-
-	if err := secfile.EnsurePrivateDir(dir); err != nil {
-		return fmt.Errorf("failed to create conversation directory: %w", err)
-	}
-
-	filename := filepath.Join(dir, fmt.Sprintf("k8s_%s.json", secfile.SafeSlug(h.ClusterName)))
-	data, err := json.MarshalIndent(h, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal conversation history: %w", err)
-	}
-
-	if err := secfile.WritePrivate(filename, data); err != nil {
-		return fmt.Errorf("failed to write conversation file: %w", err)
-	}
-
-	return nil
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Entries = convhistory.FitSize(h.Entries, convhistory.MaxBytes())
+	return convhistory.Save("k8s", h.ClusterName, h)
 }
 
 // Load loads conversation history from disk
 func (h *ConversationHistory) Load() error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-
-	dir, err := getConversationDir()
-	if err != nil {
-		return err
-	}
-
-	filename := filepath.Join(dir, fmt.Sprintf("k8s_%s.json", secfile.SafeSlug(h.ClusterName)))
-	data, err := secfile.ReadPrivate(filename)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// No history yet, that is fine
-			return nil
-		}
-		return fmt.Errorf("failed to read conversation file: %w", err)
-	}
-
-	// Unmarshal into a temporary struct to avoid overwriting the mutex
-	var loaded struct {
-		Entries     []ConversationEntry `json:"entries"`
-		ClusterName string              `json:"cluster_name"`
-		LastStatus  *ClusterStatus      `json:"last_status,omitempty"`
-	}
-
-	if err := json.Unmarshal(data, &loaded); err != nil {
-		return fmt.Errorf("failed to parse conversation history: %w", err)
-	}
-
-	h.Entries = loaded.Entries
-	h.ClusterName = loaded.ClusterName
-	h.LastStatus = loaded.LastStatus
-
-	return nil
-}
-
-// getConversationDir returns the directory for storing conversation files
-func getConversationDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-	return filepath.Join(homeDir, ".clanker", "conversations"), nil
-}
-
-// truncateText truncates text to maxLen characters, adding ellipsis if truncated
-func truncateText(text string, maxLen int) string {
-	if len(text) <= maxLen {
-		return text
-	}
-	return text[:maxLen] + "..."
+	_, err := convhistory.Load("k8s", h.ClusterName, h)
+	return err
 }
 
 // GatherClusterStatus collects basic cluster information for context
@@ -281,15 +206,11 @@ func GatherClusterStatus(ctx context.Context, client *Client) (*ClusterStatus, e
 		status.NamespaceCount = len(namespaces)
 	}
 
-	// Get pod count (across all namespaces)
-	podsOutput, err := client.Run(ctx, "get", "pods", "-A", "--no-headers")
+	// Get pod count (across all namespaces), listing only namespace and
+	// phase so large clusters stay cheap to count
+	pods, err := workloads.SummarizePods(ctx, &clientAdapter{client: client}, "", workloads.QueryOptions{AllNamespaces: true})
 	if err == nil {
-		lines := strings.Split(strings.TrimSpace(podsOutput), "\n")
-		if len(lines) == 1 && lines[0] == "" {
-			status.PodCount = 0
-		} else {
-			status.PodCount = len(lines)
-		}
+		status.PodCount = pods.Total
 	}
 
 	return status, nil
@@ -0,0 +1,245 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/qfilter"
+)
+
+// entityTimeout bounds the model call; the regex extractor answers when the
+// model is slow or unavailable
+const entityTimeout = 15 * time.Second
+
+// QueryEntities are the Kubernetes names a question mentions
+type QueryEntities struct {
+	Namespace     string   `json:"namespace"`
+	ResourceNames []string `json:"resource_names"`
+	LabelSelector string   `json:"label_selector"`
+	Container     string   `json:"container"`
+}
+
+var (
+	dns1123Label = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+	// resource names may also hold dots, e.g. a ConfigMap app.config
+	resourceNameRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9.-]{0,251}[a-z0-9])?$`)
+	selectorTermRe = regexp.MustCompile(`^!?[A-Za-z0-9./_-]+(?:\s*(?:==?|!=)\s*[A-Za-z0-9._-]*|\s+(?:in|notin)\s+\([A-Za-z0-9._,\s-]*\))?$`)
+
+	nsFlagRe          = regexp.MustCompile(`(?i)(?:^|\s)(?:-n|--namespace)[=\s]+["'` + "`" + `]?([a-z0-9][a-z0-9-]*)`)
+	nsAfterRe         = regexp.MustCompile(`(?i)\b(?:namespace|ns)\s+["'` + "`" + `]?([a-z0-9][a-z0-9-]*)`)
+	nsBeforeRe        = regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9-]*)\s+namespace\b`)
+	nsSystemRe        = regexp.MustCompile(`(?i)\b(kube-system|kube-public|kube-node-lease)\b`)
+	selectorRe        = regexp.MustCompile(`(?i)(?:^|\s)(?:-l|--selector|selector)[=\s]+["'` + "`" + `]?([A-Za-z0-9./_-]+\s*!?=\s*[A-Za-z0-9._-]+(?:,[A-Za-z0-9./_-]+\s*!?=\s*[A-Za-z0-9._-]+)*)`)
+	containerAfterRe  = regexp.MustCompile(`(?i)(?:\bcontainer\s+|(?:^|\s)(?:-c|--container)[=\s]+)["'` + "`" + `]?([a-z0-9][a-z0-9-]*)`)
+	containerBeforeRe = regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9-]*)\s+container\b`)
+	resourceRe        = regexp.MustCompile(`(?i)\b(?:pods?|deployments?|deploy|statefulsets?|sts|daemonsets?|ds|replicasets?|jobs?|cronjobs?|services?|svc|ingress(?:es)?|configmaps?|cm|secrets?|pvcs?|hpa|releases?)(?:/|\s+)["'` + "`" + `]?([a-z0-9][a-z0-9.-]*)`)
+	quotedRe          = regexp.MustCompile("[\"'`]([a-z0-9][a-z0-9.-]*)[\"'`]")
+)
+
+// entityStopwords follow "namespace", "pod" or "container" in questions
+// without naming one, as in "pods in the namespace" or "the pod is"
+var entityStopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "my": true, "our": true, "this": true, "that": true,
+	"these": true, "those": true, "each": true, "every": true, "all": true, "any": true,
+	"in": true, "on": true, "of": true, "for": true, "to": true, "from": true, "with": true,
+	"and": true, "or": true, "is": true, "are": true, "was": true, "were": true, "as": true,
+	"by": true, "which": true, "named": true, "called": true, "logs": true,
+	"status": true, "restarts": true, "restarting": true, "crashing": true, "failing": true,
+	"running": true, "pending": true, "keeps": true, "has": true, "have": true, "not": true,
+	"one": true, "same": true, "current": true, "new": true, "old": true, "it": true,
+	"older": true, "newer": true, "than": true, "across": true, "where": true, "without": true,
+	"per": true, "stuck": true, "ready": true, "evicted": true, "using": true, "be": true,
+}
+
+// ExtractEntities reads the namespace, resource names, label selector and
+// container from a question with patterns such as "in namespace shop",
+// "pod api-7d9f", "-l app=web" and "container sidecar". Label qualifiers
+// like "labeled app=web" are left to qfilter.
+func ExtractEntities(query string) QueryEntities {
+	var e QueryEntities
+
+	for _, re := range []*regexp.Regexp{nsFlagRe, nsAfterRe, nsBeforeRe, nsSystemRe} {
+		if ns := firstName(re, query); ns != "" && dns1123Label.MatchString(ns) {
+			e.Namespace = ns
+			break
+		}
+	}
+
+	if m := selectorRe.FindStringSubmatch(query); m != nil {
+		e.LabelSelector = strings.Join(strings.Fields(m[1]), "")
+	}
+
+	for _, re := range []*regexp.Regexp{containerAfterRe, containerBeforeRe} {
+		if c := firstName(re, query); c != "" && dns1123Label.MatchString(c) {
+			e.Container = c
+			break
+		}
+	}
+
+	seen := map[string]bool{e.Namespace: true, e.Container: true}
+	for _, re := range []*regexp.Regexp{resourceRe, quotedRe} {
+		for _, m := range re.FindAllStringSubmatch(query, -1) {
+			name := strings.ToLower(strings.TrimRight(m[1], "."))
+			if seen[name] || entityStopwords[name] || !resourceNameRe.MatchString(name) {
+				continue
+			}
+			seen[name] = true
+			e.ResourceNames = append(e.ResourceNames, name)
+		}
+	}
+	return e
+}
+
+// firstName returns the first non-stopword name re captures
+func firstName(re *regexp.Regexp, query string) string {
+	for _, m := range re.FindAllStringSubmatch(query, -1) {
+		if name := strings.ToLower(m[1]); !entityStopwords[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// extractEntities asks the model for the question's entities, when the
+// agent has one (see SetQueryModel), and falls back to ExtractEntities when the call fails or
+// returns nothing usable. Fields the model leaves empty or gets malformed
+// are filled from the patterns.
+func (a *Agent) extractEntities(ctx context.Context, query string) QueryEntities {
+	fallback := ExtractEntities(query)
+	ask := a.queryModel()
+	if ask == nil {
+		return fallback
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, entityTimeout)
+	defer cancel()
+	response, err := ask(ctx, entityPrompt(query))
+	if err != nil {
+		logger.Debugf("entity extraction failed, using patterns: %v", err)
+		return fallback
+	}
+	e, err := parseEntities(response)
+	if err != nil {
+		logger.Debugf("entity extraction parse failed, using patterns: %v", err)
+		return fallback
+	}
+
+	if e.Namespace == "" {
+		e.Namespace = fallback.Namespace
+	}
+	if e.LabelSelector == "" {
+		e.LabelSelector = fallback.LabelSelector
+	}
+	if e.Container == "" {
+		e.Container = fallback.Container
+	}
+	if len(e.ResourceNames) == 0 {
+		e.ResourceNames = fallback.ResourceNames
+	}
+	logger.Debugf("entities: namespace=%q names=%v selector=%q container=%q", e.Namespace, e.ResourceNames, e.LabelSelector, e.Container)
+	return e
+}
+
+func entityPrompt(query string) string {
+	return fmt.Sprintf(`Extract the Kubernetes names mentioned in this request.
+
+Request: %s
+
+Return a JSON object with exactly these fields:
+{"namespace": "", "resource_names": [], "label_selector": "", "container": ""}
+
+- namespace: the namespace the request names, or "" if none
+- resource_names: names of pods, deployments, services or other objects, not their kinds
+- label_selector: a kubectl label selector such as "app=web,tier!=cache", or ""
+- container: the container named within a pod, or ""
+
+Only use names that appear in the request; never guess. Return valid JSON only.`, query)
+}
+
+// parseEntities reads the model's JSON and drops values that are not valid
+// Kubernetes names or selectors, so they cannot reach kubectl arguments
+func parseEntities(response string) (QueryEntities, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return QueryEntities{}, fmt.Errorf("no valid JSON found in response")
+	}
+	var raw QueryEntities
+	if err := json.Unmarshal([]byte(response[start:end+1]), &raw); err != nil {
+		return QueryEntities{}, fmt.Errorf("invalid entities JSON: %w", err)
+	}
+
+	var e QueryEntities
+	if ns := strings.ToLower(strings.TrimSpace(raw.Namespace)); dns1123Label.MatchString(ns) {
+		e.Namespace = ns
+	}
+	if c := strings.ToLower(strings.TrimSpace(raw.Container)); dns1123Label.MatchString(c) {
+		e.Container = c
+	}
+	if validSelector(raw.LabelSelector) {
+		e.LabelSelector = strings.TrimSpace(raw.LabelSelector)
+	}
+	seen := map[string]bool{}
+	for _, name := range raw.ResourceNames {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !seen[name] && resourceNameRe.MatchString(name) {
+			seen[name] = true
+			e.ResourceNames = append(e.ResourceNames, name)
+		}
+	}
+	return e, nil
+}
+
+// validSelector reports whether s is a non-empty label selector made of
+// equality, set and existence terms
+func validSelector(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	// Commas inside "in (a,b)" do not separate terms
+	depth, last := 0, 0
+	for i, r := range s + "," {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				if !selectorTermRe.MatchString(strings.TrimSpace(s[last:i])) {
+					return false
+				}
+				last = i + 1
+			}
+		}
+	}
+	return depth == 0
+}
+
+// withEntities narrows opts to what the question names. A namespace in the
+// question replaces the configured one, and its label selector is ANDed
+// with any given on the command line.
+func (o QueryOptions) withEntities(e QueryEntities) QueryOptions {
+	if e.Namespace != "" {
+		o.Namespace = e.Namespace
+	}
+	o.LabelSelector = joinSelectors(o.LabelSelector, e.LabelSelector)
+	if len(o.ResourceNames) == 0 {
+		o.ResourceNames = e.ResourceNames
+	}
+	if o.Container == "" {
+		o.Container = e.Container
+	}
+	return o
+}
+
+// labelSelectorFor ANDs the label qualifiers in query, such as "labeled
+// app=web", with the selector in opts
+func labelSelectorFor(query, resourceType string, opts QueryOptions) string {
+	return joinSelectors(opts.LabelSelector, qfilter.Compile(query).KubernetesLabelSelector(resourceType))
+}
@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExtractEntities(t *testing.T) {
+	tests := []struct {
+		query string
+		want  QueryEntities
+	}{
+		{"list pods in namespace shop", QueryEntities{Namespace: "shop"}},
+		{"why is pod api-7d9f crashing in the payments namespace", QueryEntities{Namespace: "payments", ResourceNames: []string{"api-7d9f"}}},
+		{"show services -n staging -l app=web,tier=api", QueryEntities{Namespace: "staging", LabelSelector: "app=web,tier=api"}},
+		{"logs of container sidecar in pod web-0", QueryEntities{Container: "sidecar", ResourceNames: []string{"web-0"}}},
+		{"is the istio-proxy container restarting in kube-system", QueryEntities{Namespace: "kube-system", Container: "istio-proxy"}},
+		{"restart deployment/checkout in the default namespace", QueryEntities{Namespace: "default", ResourceNames: []string{"checkout"}}},
+		{"describe configmap 'app.config'", QueryEntities{ResourceNames: []string{"app.config"}}},
+		{"list pods older than 3 days", QueryEntities{}},
+		{"which namespace has the most pods", QueryEntities{}},
+	}
+	for _, tt := range tests {
+		if got := ExtractEntities(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExtractEntities(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseEntitiesDropsInvalidValues(t *testing.T) {
+	got, err := parseEntities("```json\n" + `{"namespace": "Shop", "resource_names": ["web", "web", "bad name;rm"], "label_selector": "app=web; rm -rf /", "container": "--privileged"}` + "\n```")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := QueryEntities{Namespace: "shop", ResourceNames: []string{"web"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEntities = %+v, want %+v", got, want)
+	}
+	if _, err := parseEntities("no idea"); err == nil {
+		t.Error("parseEntities without JSON should fail")
+	}
+}
+
+func TestValidSelector(t *testing.T) {
+	for s, want := range map[string]bool{
+		"app=web":                         true,
+		"app=web,tier!=cache":             true,
+		"env in (prod,staging),!canary":   true,
+		"app==web":                        true,
+		"":                                false,
+		"app=web; rm -rf /":               false,
+		"env in (prod":                    false,
+		"app=$(whoami)":                   false,
+		"kubernetes.io/hostname=node-1.a": true,
+	} {
+		if got := validSelector(s); got != want {
+			t.Errorf("validSelector(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestAgentExtractEntitiesFallsBack(t *testing.T) {
+	a := &Agent{}
+	a.SetQueryModel(func(context.Context, string) (string, error) {
+		return `{"namespace": "checkout", "resource_names": [], "label_selector": "", "container": ""}`, nil
+	})
+	got := a.extractEntities(context.Background(), "pods of the checkout app with -l app=checkout")
+	want := QueryEntities{Namespace: "checkout", LabelSelector: "app=checkout"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("extractEntities = %+v, want %+v", got, want)
+	}
+
+	a.SetQueryModel(func(context.Context, string) (string, error) { return "", errors.New("no model") })
+	if got := a.extractEntities(context.Background(), "list pods in namespace shop"); got.Namespace != "shop" {
+		t.Errorf("extractEntities after a model error = %+v, want namespace shop", got)
+	}
+}
+
+func TestWithEntities(t *testing.T) {
+	opts := QueryOptions{Namespace: "default", ListOptions: ListOptions{LabelSelector: "tier=api"}}
+	got := opts.withEntities(QueryEntities{Namespace: "shop", LabelSelector: "app=web", ResourceNames: []string{"web-0"}, Container: "app"})
+	if got.Namespace != "shop" || got.LabelSelector != "tier=api,app=web" || !reflect.DeepEqual(got.ResourceNames, []string{"web-0"}) || got.Container != "app" {
+		t.Errorf("withEntities = %+v", got)
+	}
+	if got := opts.withEntities(QueryEntities{}); got.Namespace != "default" || got.LabelSelector != "tier=api" {
+		t.Errorf("withEntities(empty) = %+v", got)
+	}
+}
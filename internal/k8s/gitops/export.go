@@ -0,0 +1,473 @@
+// Package gitops turns approved K8s plans into declarative files for a Git
+// repository watched by Argo CD or Flux, instead of applying them directly.
+package gitops
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"github.com/bgdnvk/clanker/internal/k8s/helm"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats for Helm releases. Plain manifests are always written as a
+// Kustomize base, which both tools understand.
+const (
+	FormatFlux   = "flux"
+	FormatArgoCD = "argocd"
+)
+
+// Options controls where and how a plan is exported
+type Options struct {
+	// Dir is the repository directory for the files, e.g. clusters/prod/apps
+	Dir string
+	// Format is FormatFlux (default) or FormatArgoCD
+	Format string
+	// ArgoNamespace is where Argo CD Applications live (default argocd)
+	ArgoNamespace string
+}
+
+// Export is the result of converting a plan
+type Export struct {
+	// Files maps repository paths to contents, including kustomization.yaml
+	Files map[string]string
+	// Skipped lists plan steps with no declarative form, as shell commands
+	Skipped []string
+}
+
+// ExportPlan converts the manifests, helm installs/upgrades and namespace
+// creation in a K8s plan into files. Imperative steps (scale, rollout,
+// uninstall, ...) are reported in Skipped rather than guessed at.
+func ExportPlan(plan *k8s.K8sPlan, opts Options) (*Export, error) {
+	if opts.Dir == "" {
+		opts.Dir = "gitops"
+	}
+	switch opts.Format {
+	case "":
+		opts.Format = FormatFlux
+	case FormatFlux, FormatArgoCD:
+	default:
+		return nil, fmt.Errorf("unsupported gitops format %q (use %s or %s)", opts.Format, FormatFlux, FormatArgoCD)
+	}
+	if opts.ArgoNamespace == "" {
+		opts.ArgoNamespace = "argocd"
+	}
+
+	e := &exporter{opts: opts, files: map[string]string{}, repos: map[string]string{}}
+
+	// Repositories added by the plan itself, for HelmRepository URLs
+	for _, cmd := range plan.HelmCmds {
+		args := helmArgs(cmd)
+		if len(args) >= 4 && args[0] == "repo" && args[1] == "add" {
+			e.repos[args[2]] = args[3]
+		}
+	}
+
+	for _, m := range plan.Manifests {
+		if strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		name := m.Name
+		if m.Kind != "" {
+			name = m.Kind + "-" + m.Name
+		}
+		e.add(slug(name)+".yaml", strings.TrimSpace(m.Content)+"\n")
+	}
+	for _, cmd := range plan.KubectlCmds {
+		if err := e.kubectl(cmd); err != nil {
+			return nil, err
+		}
+	}
+	for _, cmd := range plan.HelmCmds {
+		if err := e.helm(cmd); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(e.files) == 0 && len(e.remote) == 0 {
+		return nil, fmt.Errorf("plan has no steps that can be exported to GitOps (skipped: %s)", strings.Join(e.skipped, "; "))
+	}
+
+	resources := make([]string, 0, len(e.files)+len(e.remote))
+	for p := range e.files {
+		resources = append(resources, path.Base(p))
+	}
+	sort.Strings(resources)
+	resources = append(resources, e.remote...)
+	kustomization, err := yaml.Marshal(map[string]any{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"resources":  resources,
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.add("kustomization.yaml", string(kustomization))
+
+	return &Export{Files: e.files, Skipped: e.skipped}, nil
+}
+
+type exporter struct {
+	opts    Options
+	files   map[string]string
+	remote  []string
+	repos   map[string]string
+	skipped []string
+}
+
+func (e *exporter) add(name, content string) {
+	e.files[path.Join(e.opts.Dir, name)] = content
+}
+
+func (e *exporter) addObject(name string, obj map[string]any) error {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	e.add(name, string(data))
+	return nil
+}
+
+func (e *exporter) skip(binary string, args []string, why string) {
+	e.skipped = append(e.skipped, fmt.Sprintf("%s %s (%s)", binary, strings.Join(args, " "), why))
+}
+
+// kubectl exports namespace creation and applied files or URLs
+func (e *exporter) kubectl(cmd k8s.KubectlCmd) error {
+	args := cmd.Args
+	if len(args) >= 3 && args[0] == "create" && (args[1] == "namespace" || args[1] == "ns") {
+		return e.addObject("namespace-"+slug(args[2])+".yaml", map[string]any{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]any{"name": args[2]},
+		})
+	}
+	if len(args) > 0 && args[0] == "apply" {
+		source := flagValue(args, "-f", "--filename")
+		switch {
+		case strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://"):
+			e.remote = append(e.remote, source)
+			return nil
+		case source != "" && source != "-":
+			data, err := os.ReadFile(source)
+			if err != nil {
+				e.skip("kubectl", args, fmt.Sprintf("cannot read %s: %v", source, err))
+				return nil
+			}
+			e.add(slug(strings.TrimSuffix(path.Base(source), path.Ext(source)))+".yaml", string(data))
+			return nil
+		}
+	}
+	e.skip("kubectl", args, "imperative step, run it after the sync")
+	return nil
+}
+
+// helm exports install/upgrade as a Flux HelmRelease or Argo CD Application
+func (e *exporter) helm(cmd k8s.HelmCmd) error {
+	args := helmArgs(cmd)
+	if len(args) == 0 {
+		return nil
+	}
+	if args[0] == "repo" {
+		// Folded into the HelmRepository / Application source
+		return nil
+	}
+	if args[0] != "install" && args[0] != "upgrade" {
+		e.skip("helm", args, "remove or revert the files in Git instead")
+		return nil
+	}
+
+	release, err := parseHelmRelease(args)
+	if err != nil {
+		return err
+	}
+	if release.Namespace == "" {
+		release.Namespace = cmd.Namespace
+	}
+	if release.Namespace == "" {
+		release.Namespace = "default"
+	}
+
+	source, ok := e.chartSource(release.Chart)
+	if !ok {
+		e.skip("helm", args, fmt.Sprintf("chart %s has no known repository URL; commit the chart or add its repo", release.Chart))
+		return nil
+	}
+
+	if e.opts.Format == FormatArgoCD {
+		return e.argoApplication(release, source)
+	}
+	return e.fluxHelmRelease(release, source)
+}
+
+// chartSource resolves a chart reference to its repository
+func (e *exporter) chartSource(chart string) (chartSource, bool) {
+	if strings.HasPrefix(chart, "oci://") {
+		idx := strings.LastIndex(chart, "/")
+		return chartSource{RepoName: slug(path.Base(chart[:idx])), URL: chart[:idx], Chart: chart[idx+1:], OCI: true}, true
+	}
+	repo, name, ok := strings.Cut(chart, "/")
+	if !ok || strings.HasPrefix(chart, ".") || strings.HasPrefix(chart, "/") {
+		return chartSource{}, false
+	}
+	url := e.repos[repo]
+	if url == "" {
+		url = helm.KnownRepoURL(repo)
+	}
+	if url == "" {
+		return chartSource{}, false
+	}
+	return chartSource{RepoName: repo, URL: url, Chart: name}, true
+}
+
+type chartSource struct {
+	RepoName string
+	URL      string
+	Chart    string
+	OCI      bool
+}
+
+func (e *exporter) fluxHelmRelease(r helmRelease, src chartSource) error {
+	repoSpec := map[string]any{"interval": "1h", "url": src.URL}
+	if src.OCI {
+		repoSpec["type"] = "oci"
+	}
+	if err := e.addObject("helmrepository-"+slug(src.RepoName)+".yaml", map[string]any{
+		"apiVersion": "source.toolkit.fluxcd.io/v1",
+		"kind":       "HelmRepository",
+		"metadata":   map[string]any{"name": src.RepoName, "namespace": "flux-system"},
+		"spec":       repoSpec,
+	}); err != nil {
+		return err
+	}
+
+	chartSpec := map[string]any{
+		"chart":     src.Chart,
+		"sourceRef": map[string]any{"kind": "HelmRepository", "name": src.RepoName, "namespace": "flux-system"},
+	}
+	if r.Version != "" {
+		chartSpec["version"] = r.Version
+	}
+	spec := map[string]any{
+		"interval":    "10m",
+		"releaseName": r.Name,
+		"chart":       map[string]any{"spec": chartSpec},
+	}
+	if r.CreateNamespace {
+		spec["install"] = map[string]any{"createNamespace": true}
+	}
+	if r.Timeout != "" {
+		spec["timeout"] = r.Timeout
+	}
+	if len(r.Values) > 0 {
+		spec["values"] = r.Values
+	}
+	return e.addObject("helmrelease-"+slug(r.Name)+".yaml", map[string]any{
+		"apiVersion": "helm.toolkit.fluxcd.io/v2",
+		"kind":       "HelmRelease",
+		"metadata":   map[string]any{"name": r.Name, "namespace": r.Namespace},
+		"spec":       spec,
+	})
+}
+
+func (e *exporter) argoApplication(r helmRelease, src chartSource) error {
+	repoURL := src.URL
+	if src.OCI {
+		// Argo CD takes OCI registries without the scheme
+		repoURL = strings.TrimPrefix(repoURL, "oci://")
+	}
+	version := r.Version
+	if version == "" {
+		version = "*"
+	}
+	helmSpec := map[string]any{"releaseName": r.Name}
+	if len(r.Values) > 0 {
+		helmSpec["valuesObject"] = r.Values
+	}
+	spec := map[string]any{
+		"project": "default",
+		"source": map[string]any{
+			"repoURL":        repoURL,
+			"chart":          src.Chart,
+			"targetRevision": version,
+			"helm":           helmSpec,
+		},
+		"destination": map[string]any{"server": "https://kubernetes.default.svc", "namespace": r.Namespace},
+	}
+	if r.CreateNamespace {
+		spec["syncPolicy"] = map[string]any{"syncOptions": []string{"CreateNamespace=true"}}
+	}
+	return e.addObject("application-"+slug(r.Name)+".yaml", map[string]any{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata":   map[string]any{"name": r.Name, "namespace": e.opts.ArgoNamespace},
+		"spec":       spec,
+	})
+}
+
+// helmRelease is what an install/upgrade command declares
+type helmRelease struct {
+	Name            string
+	Chart           string
+	Namespace       string
+	Version         string
+	Timeout         string
+	CreateNamespace bool
+	Values          map[string]any
+}
+
+// helmValueFlags take a value as the next argument
+var helmValueFlags = map[string]bool{
+	"-n": true, "--namespace": true, "--version": true, "--set": true, "--set-string": true,
+	"-f": true, "--values": true, "--timeout": true, "--description": true,
+	"--kube-context": true, "--kubeconfig": true, "--repo": true,
+}
+
+func parseHelmRelease(args []string) (helmRelease, error) {
+	r := helmRelease{Values: map[string]any{}}
+	var positional []string
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		flag, value, hasValue := strings.Cut(arg, "=")
+		if !strings.HasPrefix(flag, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+		if !hasValue && helmValueFlags[flag] {
+			if i+1 >= len(args) {
+				return r, fmt.Errorf("helm flag %s has no value", flag)
+			}
+			i++
+			value = args[i]
+			hasValue = true
+		}
+		switch flag {
+		case "-n", "--namespace":
+			r.Namespace = value
+		case "--version":
+			r.Version = value
+		case "--timeout":
+			r.Timeout = value
+		case "--create-namespace":
+			r.CreateNamespace = true
+		case "--set", "--set-string":
+			for _, pair := range strings.Split(value, ",") {
+				key, v, ok := strings.Cut(pair, "=")
+				if !ok {
+					return r, fmt.Errorf("invalid --set value %q", pair)
+				}
+				var parsed any = v
+				if flag == "--set" {
+					parsed = scalar(v)
+				}
+				setPath(r.Values, strings.Split(key, "."), parsed)
+			}
+		case "-f", "--values":
+			data, err := os.ReadFile(value)
+			if err != nil {
+				return r, fmt.Errorf("failed to read values file %s: %w", value, err)
+			}
+			var values map[string]any
+			if err := yaml.Unmarshal(data, &values); err != nil {
+				return r, fmt.Errorf("failed to parse values file %s: %w", value, err)
+			}
+			mergeValues(r.Values, values)
+		}
+	}
+	if len(positional) < 2 {
+		return r, fmt.Errorf("helm %s needs a release and a chart: %s", args[0], strings.Join(args, " "))
+	}
+	r.Name, r.Chart = positional[0], positional[1]
+	return r, nil
+}
+
+// helmArgs returns the raw args of a helm step, rebuilding them from the
+// structured fields when the plan has none
+func helmArgs(cmd k8s.HelmCmd) []string {
+	if len(cmd.Args) > 0 {
+		return cmd.Args
+	}
+	if cmd.Action == "" {
+		return nil
+	}
+	args := []string{cmd.Action, cmd.Release, cmd.Chart}
+	if cmd.Namespace != "" {
+		args = append(args, "-n", cmd.Namespace)
+	}
+	if cmd.Version != "" {
+		args = append(args, "--version", cmd.Version)
+	}
+	for _, s := range cmd.SetValues {
+		args = append(args, "--set", s)
+	}
+	if cmd.ValuesFile != "" {
+		args = append(args, "-f", cmd.ValuesFile)
+	}
+	return args
+}
+
+func setPath(values map[string]any, keys []string, value any) {
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := values[key].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			values[key] = next
+		}
+		values = next
+	}
+	values[keys[len(keys)-1]] = value
+}
+
+func mergeValues(dst, src map[string]any) {
+	for k, v := range src {
+		if srcMap, ok := v.(map[string]any); ok {
+			if dstMap, ok := dst[k].(map[string]any); ok {
+				mergeValues(dstMap, srcMap)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// scalar types a --set value the way helm does for the common cases
+func scalar(v string) any {
+	switch v {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return n
+	}
+	return v
+}
+
+func flagValue(args []string, names ...string) string {
+	for i, arg := range args {
+		for _, name := range names {
+			if arg == name && i+1 < len(args) {
+				return args[i+1]
+			}
+			if v, ok := strings.CutPrefix(arg, name+"="); ok {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slug(s string) string {
+	return strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
@@ -0,0 +1,144 @@
+package gitops
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s"
+	"gopkg.in/yaml.v3"
+)
+
+func testPlan() *k8s.K8sPlan {
+	return &k8s.K8sPlan{
+		Summary: "Install postgres",
+		HelmCmds: []k8s.HelmCmd{
+			{Action: "repo", Args: []string{"repo", "add", "acme", "https://charts.acme.io", "--force-update"}},
+			{Action: "install", Args: []string{"install", "db", "bitnami/postgresql", "-n", "data", "--create-namespace",
+				"--version", "16.4.1", "--set", "auth.database=app", "--set", "primary.persistence.size=8Gi,replicas=2", "--wait", "--timeout", "5m0s"}},
+			{Action: "upgrade", Args: []string{"upgrade", "--install", "cache", "acme/redis", "--namespace=data"}},
+			{Action: "uninstall", Args: []string{"uninstall", "old", "-n", "data"}},
+		},
+		KubectlCmds: []k8s.KubectlCmd{
+			{Args: []string{"create", "namespace", "data"}},
+			{Args: []string{"apply", "-f", "https://example.com/crds.yaml"}},
+			{Args: []string{"scale", "deployment", "web", "--replicas=3"}},
+		},
+		Manifests: []k8s.Manifest{{Kind: "ConfigMap", Name: "settings", Content: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n"}},
+	}
+}
+
+func decode(t *testing.T, content string) map[string]any {
+	t.Helper()
+	var obj map[string]any
+	if err := yaml.Unmarshal([]byte(content), &obj); err != nil {
+		t.Fatalf("invalid YAML: %v\n%s", err, content)
+	}
+	return obj
+}
+
+func TestExportPlanFlux(t *testing.T) {
+	export, err := ExportPlan(testPlan(), Options{Dir: "clusters/prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := decode(t, export.Files["clusters/prod/helmrelease-db.yaml"])
+	spec := release["spec"].(map[string]any)
+	chart := spec["chart"].(map[string]any)["spec"].(map[string]any)
+	if release["kind"] != "HelmRelease" || chart["chart"] != "postgresql" || chart["version"] != "16.4.1" {
+		t.Errorf("helmrelease = %v", release)
+	}
+	values := spec["values"].(map[string]any)
+	if values["auth"].(map[string]any)["database"] != "app" || values["replicas"] != 2 ||
+		values["primary"].(map[string]any)["persistence"].(map[string]any)["size"] != "8Gi" {
+		t.Errorf("values = %v", values)
+	}
+	if release["metadata"].(map[string]any)["namespace"] != "data" || spec["install"] == nil {
+		t.Errorf("release metadata/install = %v", release)
+	}
+
+	repo := decode(t, export.Files["clusters/prod/helmrepository-acme.yaml"])
+	if repo["spec"].(map[string]any)["url"] != "https://charts.acme.io" {
+		t.Errorf("repo added by the plan should be used: %v", repo)
+	}
+	if _, ok := export.Files["clusters/prod/helmrepository-bitnami.yaml"]; !ok {
+		t.Error("well-known repositories should get a HelmRepository")
+	}
+
+	kustomization := decode(t, export.Files["clusters/prod/kustomization.yaml"])
+	resources := kustomization["resources"].([]any)
+	joined := ""
+	for _, r := range resources {
+		joined += r.(string) + " "
+	}
+	for _, want := range []string{"configmap-settings.yaml", "namespace-data.yaml", "helmrelease-cache.yaml", "https://example.com/crds.yaml"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("kustomization resources %v missing %s", resources, want)
+		}
+	}
+
+	if len(export.Skipped) != 2 || !strings.HasPrefix(export.Skipped[0], "kubectl scale") || !strings.HasPrefix(export.Skipped[1], "helm uninstall") {
+		t.Errorf("skipped = %v", export.Skipped)
+	}
+}
+
+func TestExportPlanArgoCD(t *testing.T) {
+	export, err := ExportPlan(testPlan(), Options{Format: FormatArgoCD})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := decode(t, export.Files["gitops/application-db.yaml"])
+	spec := app["spec"].(map[string]any)
+	source := spec["source"].(map[string]any)
+	if app["kind"] != "Application" || app["metadata"].(map[string]any)["namespace"] != "argocd" {
+		t.Errorf("application = %v", app)
+	}
+	if source["repoURL"] != "https://charts.bitnami.com/bitnami" || source["chart"] != "postgresql" || source["targetRevision"] != "16.4.1" {
+		t.Errorf("source = %v", source)
+	}
+	if spec["destination"].(map[string]any)["namespace"] != "data" || spec["syncPolicy"] == nil {
+		t.Errorf("spec = %v", spec)
+	}
+	if _, ok := export.Files["gitops/helmrepository-bitnami.yaml"]; ok {
+		t.Error("Argo CD output should not include Flux HelmRepositories")
+	}
+}
+
+func TestExportPlanValuesFileAndLocalChart(t *testing.T) {
+	valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(valuesFile, []byte("image:\n  tag: v2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	plan := &k8s.K8sPlan{HelmCmds: []k8s.HelmCmd{
+		{Args: []string{"install", "web", "oci://ghcr.io/acme/charts/web", "-f", valuesFile, "--set", "image.pullPolicy=Always"}},
+		{Args: []string{"install", "local", "./chart"}},
+	}}
+	export, err := ExportPlan(plan, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := decode(t, export.Files["gitops/helmrelease-web.yaml"])
+	image := release["spec"].(map[string]any)["values"].(map[string]any)["image"].(map[string]any)
+	if image["tag"] != "v2" || image["pullPolicy"] != "Always" {
+		t.Errorf("image values = %v", image)
+	}
+	repo := decode(t, export.Files["gitops/helmrepository-charts.yaml"])
+	if spec := repo["spec"].(map[string]any); spec["type"] != "oci" || spec["url"] != "oci://ghcr.io/acme/charts" {
+		t.Errorf("oci repo = %v", repo)
+	}
+	if len(export.Skipped) != 1 || !strings.Contains(export.Skipped[0], "./chart") {
+		t.Errorf("local charts should be skipped: %v", export.Skipped)
+	}
+}
+
+func TestExportPlanNothingDeclarative(t *testing.T) {
+	plan := &k8s.K8sPlan{KubectlCmds: []k8s.KubectlCmd{{Args: []string{"rollout", "restart", "deployment/web"}}}}
+	if _, err := ExportPlan(plan, Options{}); err == nil {
+		t.Error("expected error when nothing can be exported")
+	}
+	if _, err := ExportPlan(testPlan(), Options{Format: "jenkins"}); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
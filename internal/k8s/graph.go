@@ -0,0 +1,244 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Graph formats accepted by Graph.Render
+const (
+	GraphFormatDOT     = "dot"
+	GraphFormatMermaid = "mermaid"
+)
+
+// Kinds of the nodes in a workload graph
+const (
+	GraphKindIngress   = "Ingress"
+	GraphKindService   = "Service"
+	GraphKindPod       = "Pod"
+	GraphKindPVC       = "PersistentVolumeClaim"
+	GraphKindPV        = "PersistentVolume"
+	GraphKindConfigMap = "ConfigMap"
+	GraphKindSecret    = "Secret"
+	GraphKindNode      = "Node"
+)
+
+// GraphNode is one resource in a workload graph
+type GraphNode struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// GraphEdge points from a resource to one it routes to, mounts or runs on
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Graph is the dependency graph of a cluster's workloads: Ingress to
+// Service to Pod, and each Pod to its PVCs, ConfigMaps, Secrets and Node
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+
+	index map[string]bool
+	edges map[GraphEdge]bool
+}
+
+// BuildGraph builds the workload graph of resources, limited to namespace
+// when it is set. Cluster-scoped nodes and PVs appear only when something
+// in the graph points at them.
+func BuildGraph(resources *ClusterResources, namespace string) *Graph {
+	g := &Graph{index: map[string]bool{}, edges: map[GraphEdge]bool{}}
+	inScope := func(ns string) bool { return namespace == "" || ns == namespace }
+
+	for _, ing := range resources.Ingresses {
+		if !inScope(ing.Namespace) {
+			continue
+		}
+		from := g.add(GraphKindIngress, ing.Namespace, ing.Name)
+		for _, rule := range ing.Rules {
+			if rule.ServiceName != "" {
+				g.link(from, g.add(GraphKindService, ing.Namespace, rule.ServiceName))
+			}
+		}
+	}
+
+	for _, svc := range resources.Services {
+		if !inScope(svc.Namespace) {
+			continue
+		}
+		from := g.add(GraphKindService, svc.Namespace, svc.Name)
+		if len(svc.Selector) == 0 {
+			continue
+		}
+		for _, pod := range resources.Pods {
+			if pod.Namespace == svc.Namespace && selects(svc.Selector, pod.Labels) {
+				g.link(from, g.add(GraphKindPod, pod.Namespace, pod.Name))
+			}
+		}
+	}
+
+	for _, pod := range resources.Pods {
+		if !inScope(pod.Namespace) {
+			continue
+		}
+		from := g.add(GraphKindPod, pod.Namespace, pod.Name)
+		for _, vol := range pod.Volumes {
+			if vol.Source == "" {
+				continue
+			}
+			switch vol.Type {
+			case "pvc":
+				g.link(from, g.add(GraphKindPVC, pod.Namespace, vol.Source))
+			case "configMap":
+				g.link(from, g.add(GraphKindConfigMap, pod.Namespace, vol.Source))
+			case "secret":
+				g.link(from, g.add(GraphKindSecret, pod.Namespace, vol.Source))
+			}
+		}
+		if pod.Node != "" {
+			g.link(from, g.add(GraphKindNode, "", pod.Node))
+		}
+	}
+
+	for _, pvc := range resources.PVCs {
+		if !inScope(pvc.Namespace) {
+			continue
+		}
+		from := g.add(GraphKindPVC, pvc.Namespace, pvc.Name)
+		if pvc.Volume != "" {
+			g.link(from, g.add(GraphKindPV, "", pvc.Volume))
+		}
+	}
+
+	for _, cm := range resources.ConfigMaps {
+		// kube-root-ca.crt is in every namespace and mounted by no pod
+		// directly, so it only adds noise
+		if inScope(cm.Namespace) && cm.Name != "kube-root-ca.crt" {
+			g.add(GraphKindConfigMap, cm.Namespace, cm.Name)
+		}
+	}
+
+	return g
+}
+
+// Render returns the graph in format, GraphFormatDOT or GraphFormatMermaid
+func (g *Graph) Render(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case GraphFormatDOT:
+		return g.DOT(), nil
+	case GraphFormatMermaid:
+		return g.Mermaid(), nil
+	}
+	return "", fmt.Errorf("unsupported graph format %q (use dot or mermaid)", format)
+}
+
+// graphShapes are the DOT shapes of each kind
+var graphShapes = map[string]string{
+	GraphKindIngress:   "hexagon",
+	GraphKindService:   "ellipse",
+	GraphKindPod:       "box",
+	GraphKindPVC:       "cylinder",
+	GraphKindPV:        "cylinder",
+	GraphKindConfigMap: "note",
+	GraphKindSecret:    "note",
+	GraphKindNode:      "box3d",
+}
+
+// DOT renders the graph for Graphviz, e.g. dot -Tsvg
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph workloads {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\"];\n")
+	for i, node := range g.Nodes {
+		fmt.Fprintf(&b, "  n%d [label=\"%s\", shape=%s];\n", i, dotEscape(node.label()), graphShapes[node.Kind])
+	}
+	ids := g.positions()
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  n%d -> n%d;\n", ids[edge.From], ids[edge.To])
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart, which GitHub and most
+// Markdown viewers draw inline
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, node := range g.Nodes {
+		open, close := "[", "]"
+		switch node.Kind {
+		case GraphKindIngress:
+			open, close = "{{", "}}"
+		case GraphKindService:
+			open, close = "([", "])"
+		case GraphKindPVC, GraphKindPV:
+			open, close = "[(", ")]"
+		}
+		fmt.Fprintf(&b, "  n%d%s\"%s\"%s\n", i, open, mermaidEscape(node.label()), close)
+	}
+	ids := g.positions()
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  n%d --> n%d\n", ids[edge.From], ids[edge.To])
+	}
+	return b.String()
+}
+
+// add returns the ID of the node for a resource, adding it the first time
+func (g *Graph) add(kind, namespace, name string) string {
+	id := kind + "/" + namespace + "/" + name
+	if !g.index[id] {
+		g.index[id] = true
+		g.Nodes = append(g.Nodes, GraphNode{ID: id, Kind: kind, Name: name, Namespace: namespace})
+	}
+	return id
+}
+
+// link adds an edge once
+func (g *Graph) link(from, to string) {
+	edge := GraphEdge{From: from, To: to}
+	if !g.edges[edge] {
+		g.edges[edge] = true
+		g.Edges = append(g.Edges, edge)
+	}
+}
+
+// positions maps node IDs to their index, which names them in the output.
+// Resource names can hold characters neither format allows in an ID.
+func (g *Graph) positions() map[string]int {
+	ids := make(map[string]int, len(g.Nodes))
+	for i, node := range g.Nodes {
+		ids[node.ID] = i
+	}
+	return ids
+}
+
+func (n GraphNode) label() string {
+	if n.Namespace == "" {
+		return n.Kind + "\n" + n.Name
+	}
+	return n.Kind + "\n" + n.Namespace + "/" + n.Name
+}
+
+// selects reports whether a service selector matches pod labels
+func selects(selector, labels map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", "<br/>").Replace(s)
+}
@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+)
+
+func graphResources() *ClusterResources {
+	return &ClusterResources{
+		Ingresses: []ClusterIngressInfo{{
+			Name: "shop", Namespace: "shop",
+			Rules: []ClusterIngressRuleInfo{{Path: "/", ServiceName: "web"}},
+		}},
+		Services: []ClusterServiceInfo{
+			{Name: "web", Namespace: "shop", Selector: map[string]string{"app": "web"}},
+			{Name: "web", Namespace: "other", Selector: map[string]string{"app": "web"}},
+		},
+		Pods: []ClusterPodInfo{
+			{
+				Name: "web-1", Namespace: "shop", Node: "node-a",
+				Labels: map[string]string{"app": "web", "pod-template-hash": "abc"},
+				Volumes: []ClusterPodVolumeInfo{
+					{Name: "data", Type: "pvc", Source: "web-data"},
+					{Name: "cfg", Type: "configMap", Source: "web-config"},
+					{Name: "tls", Type: "secret", Source: "web-tls"},
+					{Name: "tmp", Type: "emptyDir"},
+				},
+			},
+			{Name: "worker-1", Namespace: "shop", Node: "node-b", Labels: map[string]string{"app": "worker"}},
+			{Name: "web-1", Namespace: "other", Labels: map[string]string{"app": "web"}},
+		},
+		PVCs:       []ClusterPVCInfo{{Name: "web-data", Namespace: "shop", Volume: "pv-123"}},
+		ConfigMaps: []ClusterConfigMapInfo{{Name: "web-config", Namespace: "shop"}, {Name: "kube-root-ca.crt", Namespace: "shop"}},
+	}
+}
+
+func TestBuildGraph(t *testing.T) {
+	g := BuildGraph(graphResources(), "shop")
+
+	edges := map[string]bool{}
+	for _, e := range g.Edges {
+		edges[e.From+" -> "+e.To] = true
+	}
+	for _, want := range []string{
+		"Ingress/shop/shop -> Service/shop/web",
+		"Service/shop/web -> Pod/shop/web-1",
+		"Pod/shop/web-1 -> PersistentVolumeClaim/shop/web-data",
+		"Pod/shop/web-1 -> ConfigMap/shop/web-config",
+		"Pod/shop/web-1 -> Secret/shop/web-tls",
+		"Pod/shop/web-1 -> Node//node-a",
+		"Pod/shop/worker-1 -> Node//node-b",
+		"PersistentVolumeClaim/shop/web-data -> PersistentVolume//pv-123",
+	} {
+		if !edges[want] {
+			t.Errorf("missing edge %s", want)
+		}
+	}
+	if len(edges) != 8 {
+		t.Errorf("got %d edges, want 8: %v", len(edges), g.Edges)
+	}
+	for _, n := range g.Nodes {
+		if n.Namespace == "other" || n.Name == "kube-root-ca.crt" {
+			t.Errorf("unexpected node %s", n.ID)
+		}
+	}
+}
+
+func TestGraphRender(t *testing.T) {
+	g := BuildGraph(graphResources(), "shop")
+
+	dot, err := g.Render("dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dot, "digraph workloads {\n") || !strings.Contains(dot, `n0 [label="Ingress\nshop/shop", shape=hexagon];`) || !strings.Contains(dot, "n0 -> n1;") {
+		t.Errorf("dot output:\n%s", dot)
+	}
+
+	mermaid, err := g.Render("Mermaid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(mermaid, "flowchart LR\n") || !strings.Contains(mermaid, `n1(["Service<br/>shop/web"])`) || !strings.Contains(mermaid, "n0 --> n1") {
+		t.Errorf("mermaid output:\n%s", mermaid)
+	}
+
+	if _, err := g.Render("png"); err == nil {
+		t.Error("png accepted")
+	}
+}
@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"fmt"
+
+	"github.com/bgdnvk/clanker/internal/k8s/policy"
+)
+
+// PlanOperations lists the changes a plan makes for policy checks. Commands
+// and manifests without a namespace land in defaultNamespace.
+func PlanOperations(plan *K8sPlan, defaultNamespace string) []policy.Operation {
+	var ops []policy.Operation
+	for _, cmd := range plan.KubectlCmds {
+		args := cmd.Args
+		if len(args) > 0 && args[0] == "kubectl" {
+			args = args[1:]
+		}
+		ns := cmd.Namespace
+		if ns == "" {
+			ns = defaultNamespace
+		}
+		if op, ok := policy.FromKubectl(args, ns); ok {
+			ops = append(ops, op)
+		}
+	}
+	for _, cmd := range plan.HelmCmds {
+		ns := cmd.Namespace
+		if ns == "" {
+			ns = defaultNamespace
+		}
+		args := cmd.Args
+		if len(args) > 0 && args[0] == "helm" {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			args = []string{cmd.Action, cmd.Release}
+		}
+		if op, ok := policy.FromHelm(args, ns); ok {
+			ops = append(ops, op)
+		}
+	}
+	for _, m := range plan.Manifests {
+		ns := m.Namespace
+		if ns == "" {
+			ns = defaultNamespace
+		}
+		ops = append(ops, policy.FromManifest("apply", m.Content, ns)...)
+	}
+	return ops
+}
+
+// previewPolicy warns about operations the policy would refuse or hold for
+// approval. A policy file that fails to load is reported, not ignored.
+func (a *Agent) previewPolicy(plan *K8sPlan, namespace string) {
+	p, err := policy.Load()
+	if err != nil {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("policy not checked: %v", err))
+		return
+	}
+	violations := p.Check(PlanOperations(plan, namespace))
+	for _, v := range violations {
+		switch v.Action {
+		case policy.ActionDeny:
+			plan.Warnings = append(plan.Warnings, "policy denies: "+v.String())
+		case policy.ActionApprove:
+			plan.Warnings = append(plan.Warnings, "policy requires approval: "+v.String())
+		}
+	}
+	if denied := policy.Denied(violations); len(denied) > 0 {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("%d operations violate the policy; apply is refused unless --override-policy is given", len(denied)))
+	}
+}
+
+// enforcePolicy refuses a plan the policy denies unless opts override it
+func (a *Agent) enforcePolicy(plan *K8sPlan, opts ApplyOptions) error {
+	p, err := policy.Load()
+	if err != nil {
+		return err
+	}
+	namespace := ""
+	if a.client != nil {
+		namespace = a.client.namespace
+	}
+	return policy.Enforce(p.Check(PlanOperations(plan, namespace)), policy.EnforceOptions{
+		Override: opts.OverridePolicy,
+		Approve:  opts.ApprovePolicy,
+		Source:   "k8s-agent",
+		Summary:  plan.Summary,
+	})
+}
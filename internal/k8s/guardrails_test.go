@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePolicy installs a policy file under a temporary HOME
+func writePolicy(t *testing.T, content string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".clanker"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".clanker", "policy.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
+func TestPlanOperations(t *testing.T) {
+	plan := &K8sPlan{
+		KubectlCmds: []KubectlCmd{
+			{Args: []string{"kubectl", "get", "pods"}},
+			{Args: []string{"delete", "pvc", "data"}, Namespace: "shop"},
+		},
+		HelmCmds:  []HelmCmd{{Action: "install", Release: "redis", Namespace: "cache"}},
+		Manifests: []Manifest{{Kind: "Deployment", Name: "web", Content: validDeployment}},
+	}
+
+	var got []string
+	for _, op := range PlanOperations(plan, "team-a") {
+		got = append(got, op.String())
+	}
+	want := "delete persistentvolumeclaims/data in shop,install helmreleases/redis in cache,apply deployments/web in team-a"
+	if strings.Join(got, ",") != want {
+		t.Errorf("operations = %v", got)
+	}
+}
+
+func TestApplyPlanEnforcesPolicy(t *testing.T) {
+	fakeKubectl(t)
+	home := writePolicy(t, "protected_namespaces: [kube-system]\n")
+
+	a := &Agent{client: NewClient("", "", false)}
+	plan := &K8sPlan{
+		Summary:     "restart dns",
+		KubectlCmds: []KubectlCmd{{Args: []string{"rollout", "restart", "deployment/coredns"}, Namespace: "kube-system"}},
+	}
+
+	a.previewPolicy(plan, "")
+	if len(plan.Warnings) != 2 || !strings.Contains(plan.Warnings[0], "protected namespace kube-system") {
+		t.Errorf("warnings = %v", plan.Warnings)
+	}
+
+	err := a.ApplyPlan(context.Background(), plan, ApplyOptions{})
+	if err == nil || !strings.Contains(err.Error(), "--override-policy") {
+		t.Fatalf("error = %v, want a policy refusal", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".clanker", "audit.log")); !os.IsNotExist(err) {
+		t.Errorf("refusal wrote the audit log: %v", err)
+	}
+
+	if err := a.ApplyPlan(context.Background(), plan, ApplyOptions{OverridePolicy: true}); err != nil {
+		t.Fatalf("override: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(home, ".clanker", "audit.log"))
+	if err != nil || !strings.Contains(string(data), `"event":"policy_override"`) || !strings.Contains(string(data), "restart dns") {
+		t.Errorf("audit log = %s, %v", data, err)
+	}
+}
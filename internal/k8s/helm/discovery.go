@@ -0,0 +1,405 @@
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// artifactHubAPI is the ArtifactHub REST endpoint; tests point it at a fake
+var artifactHubAPI = "https://artifacthub.io/api/v1"
+
+var artifactHubClient = &http.Client{Timeout: 15 * time.Second}
+
+// Chart sources
+const (
+	ChartSourceRepo        = "repo"
+	ChartSourceArtifactHub = "artifacthub"
+)
+
+// DiscoverOptions controls chart discovery
+type DiscoverOptions struct {
+	Limit           int
+	SkipArtifactHub bool
+}
+
+// DiscoverCharts searches the configured Helm repositories and ArtifactHub
+// for keyword and returns the results ranked best first. Either source may
+// fail on its own; an error is returned only when both do.
+func (m *ChartManager) DiscoverCharts(ctx context.Context, keyword string, opts DiscoverOptions) ([]ChartSearchResult, error) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return nil, fmt.Errorf("chart keyword required")
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+
+	var results []ChartSearchResult
+	var errs []string
+
+	local, err := m.searchConfiguredRepos(ctx, keyword)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	results = append(results, local...)
+
+	if !opts.SkipArtifactHub {
+		hub, err := searchArtifactHub(ctx, keyword, opts.Limit*2)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		results = mergeChartResults(results, hub)
+	}
+
+	if len(results) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("chart search failed: %s", strings.Join(errs, "; "))
+	}
+	if m.debug && len(errs) > 0 {
+		fmt.Printf("[helm] chart search partially failed: %s\n", strings.Join(errs, "; "))
+	}
+
+	rankChartResults(results, keyword)
+	if len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	return results, nil
+}
+
+// ChartVersions lists the published versions of a search result, newest first
+func (m *ChartManager) ChartVersions(ctx context.Context, chart ChartSearchResult, limit int) ([]ChartVersion, error) {
+	var versions []ChartVersion
+	if chart.Source == ChartSourceRepo {
+		output, err := m.client.Run(ctx, "search", "repo", chart.Ref(), "--versions", "-o", "json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions of %s: %w", chart.Ref(), err)
+		}
+		charts, err := m.parseChartList([]byte(output))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range charts {
+			// helm search matches substrings, so keep only this chart
+			if c.Name == chart.Ref() {
+				versions = append(versions, ChartVersion{Version: c.Version, AppVersion: c.AppVersion})
+			}
+		}
+	} else {
+		var err error
+		versions, err = artifactHubVersions(ctx, chart.Repo, chart.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if limit > 0 && len(versions) > limit {
+		versions = versions[:limit]
+	}
+	return versions, nil
+}
+
+// InstallPlanForChart seeds an install plan for a discovered chart: the
+// repository is added when it is not configured yet, the version is pinned,
+// and well-known charts get conservative default values.
+func (m *ChartManager) InstallPlanForChart(chart ChartSearchResult, release, namespace string) *HelmPlan {
+	if release == "" {
+		release = chart.Name
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	defaults := recommendedChartValues(chart)
+	plan := NewReleaseManager(m.client, m.debug).InstallReleasePlan(InstallOptions{
+		ReleaseName:     release,
+		Chart:           chart.Ref(),
+		Namespace:       namespace,
+		CreateNamespace: true,
+		Version:         chart.Version,
+		Set:             defaults,
+		Wait:            true,
+		Timeout:         5 * time.Minute,
+	})
+
+	// InstallReleasePlan only knows a fixed set of repositories
+	hasRepoStep := len(plan.Steps) > 1
+	if chart.Source == ChartSourceArtifactHub && !hasRepoStep && !chart.IsOCI() && chart.RepoURL != "" {
+		repoSteps := m.AddRepoPlan(AddRepoOptions{Name: chart.Repo, URL: chart.RepoURL}).Steps
+		plan.Steps = append(repoSteps, plan.Steps...)
+		plan.Notes = append(plan.Notes, fmt.Sprintf("Repository %s (%s) will be added", chart.Repo, chart.RepoURL))
+	}
+
+	if chart.Version != "" {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("Pinned to chart version %s (app %s)", chart.Version, chart.AppVersion))
+	}
+	if len(defaults) > 0 {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("Default values: %s", strings.Join(defaults, ", ")))
+	} else {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("Chart defaults are used as-is; review them with 'helm show values %s'", chart.Ref()))
+	}
+	if chart.Deprecated {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("Chart %s is deprecated upstream", chart.Ref()))
+	}
+	return plan
+}
+
+// searchConfiguredRepos runs helm search repo and tags the results
+func (m *ChartManager) searchConfiguredRepos(ctx context.Context, keyword string) ([]ChartSearchResult, error) {
+	charts, err := m.SearchCharts(ctx, keyword)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]ChartSearchResult, 0, len(charts))
+	for _, c := range charts {
+		repo, name, ok := strings.Cut(c.Name, "/")
+		if !ok {
+			continue
+		}
+		c.Name = name
+		results = append(results, ChartSearchResult{ChartInfo: c, Repo: repo, Source: ChartSourceRepo})
+	}
+	return results, nil
+}
+
+// mergeChartResults adds ArtifactHub results, folding duplicates of a
+// configured-repo chart into the local entry so it keeps the hub metadata
+func mergeChartResults(local, hub []ChartSearchResult) []ChartSearchResult {
+	index := make(map[string]int, len(local))
+	for i, r := range local {
+		index[r.Ref()] = i
+	}
+	for _, h := range hub {
+		if i, ok := index[h.Ref()]; ok {
+			local[i].Stars = h.Stars
+			local[i].Official = h.Official
+			local[i].VerifiedPublisher = h.VerifiedPublisher
+			local[i].RepoURL = h.RepoURL
+			continue
+		}
+		index[h.Ref()] = len(local)
+		local = append(local, h)
+	}
+	return local
+}
+
+// rankChartResults scores results by name match, trust signals and
+// popularity. Charts from repositories already configured get a bump since
+// they are what the cluster owner already uses.
+func rankChartResults(results []ChartSearchResult, keyword string) {
+	keyword = strings.ToLower(keyword)
+	for i := range results {
+		r := &results[i]
+		name := strings.ToLower(r.Name)
+		score := 0
+		switch {
+		case name == keyword:
+			score += 50
+		case strings.HasPrefix(name, keyword):
+			score += 35
+		case strings.Contains(name, keyword):
+			score += 25
+		case strings.Contains(strings.ToLower(r.Description), keyword):
+			score += 10
+		}
+		if r.Source == ChartSourceRepo {
+			score += 15
+		}
+		if r.Official {
+			score += 20
+		}
+		if r.VerifiedPublisher {
+			score += 10
+		}
+		score += min(r.Stars/10, 20)
+		if r.Deprecated {
+			score -= 100
+		}
+		r.Score = score
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Stars > results[j].Stars
+	})
+}
+
+// recommendedChartValues returns conservative --set values for charts whose
+// value layout is well known. Other charts install with their own defaults.
+func recommendedChartValues(chart ChartSearchResult) []string {
+	defaults := map[string][]string{
+		"bitnami/postgresql":                         {"auth.database=app", "primary.persistence.size=8Gi", "primary.resourcesPreset=small"},
+		"bitnami/mysql":                              {"auth.database=app", "primary.persistence.size=8Gi", "primary.resourcesPreset=small"},
+		"bitnami/mariadb":                            {"auth.database=app", "primary.persistence.size=8Gi", "primary.resourcesPreset=small"},
+		"bitnami/mongodb":                            {"architecture=standalone", "persistence.size=8Gi", "resourcesPreset=small"},
+		"bitnami/redis":                              {"architecture=standalone", "master.persistence.size=8Gi", "master.resourcesPreset=small"},
+		"bitnami/rabbitmq":                           {"replicaCount=1", "persistence.size=8Gi", "resourcesPreset=small"},
+		"bitnami/kafka":                              {"controller.replicaCount=1", "controller.persistence.size=8Gi"},
+		"bitnami/nginx":                              {"service.type=ClusterIP"},
+		"grafana/grafana":                            {"persistence.enabled=true", "persistence.size=5Gi"},
+		"ingress-nginx/ingress-nginx":                {"controller.replicaCount=2", "controller.metrics.enabled=true"},
+		"jetstack/cert-manager":                      {"crds.enabled=true"},
+		"prometheus-community/kube-prometheus-stack": {"grafana.enabled=true", "prometheus.prometheusSpec.retention=7d"},
+	}
+	return defaults[chart.Repo+"/"+chart.Name]
+}
+
+// artifactHubPackage is the subset of an ArtifactHub package we use
+type artifactHubPackage struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	AppVersion  string `json:"app_version"`
+	Deprecated  bool   `json:"deprecated"`
+	Stars       int    `json:"stars"`
+	Official    bool   `json:"official"`
+	HomeURL     string `json:"home_url"`
+	Repository  struct {
+		Name              string `json:"name"`
+		URL               string `json:"url"`
+		Official          bool   `json:"official"`
+		VerifiedPublisher bool   `json:"verified_publisher"`
+	} `json:"repository"`
+	AvailableVersions []struct {
+		Version    string `json:"version"`
+		Prerelease bool   `json:"prerelease"`
+	} `json:"available_versions"`
+}
+
+// searchArtifactHub queries ArtifactHub for Helm charts (kind 0)
+func searchArtifactHub(ctx context.Context, keyword string, limit int) ([]ChartSearchResult, error) {
+	query := url.Values{}
+	query.Set("ts_query_web", keyword)
+	query.Set("kind", "0")
+	query.Set("facets", "false")
+	query.Set("limit", fmt.Sprintf("%d", min(limit, 60)))
+	query.Set("offset", "0")
+
+	var body struct {
+		Packages []artifactHubPackage `json:"packages"`
+	}
+	if err := getArtifactHub(ctx, "/packages/search?"+query.Encode(), &body); err != nil {
+		return nil, err
+	}
+
+	results := make([]ChartSearchResult, 0, len(body.Packages))
+	for _, p := range body.Packages {
+		results = append(results, ChartSearchResult{
+			ChartInfo: ChartInfo{
+				Name:        p.Name,
+				Version:     p.Version,
+				AppVersion:  p.AppVersion,
+				Description: p.Description,
+				Home:        p.HomeURL,
+				Deprecated:  p.Deprecated,
+			},
+			Repo:              p.Repository.Name,
+			RepoURL:           p.Repository.URL,
+			Source:            ChartSourceArtifactHub,
+			Stars:             p.Stars,
+			Official:          p.Official || p.Repository.Official,
+			VerifiedPublisher: p.Repository.VerifiedPublisher,
+		})
+	}
+	return results, nil
+}
+
+// artifactHubVersions lists a package's stable versions
+func artifactHubVersions(ctx context.Context, repo, name string) ([]ChartVersion, error) {
+	var pkg artifactHubPackage
+	path := fmt.Sprintf("/packages/helm/%s/%s", url.PathEscape(repo), url.PathEscape(name))
+	if err := getArtifactHub(ctx, path, &pkg); err != nil {
+		return nil, err
+	}
+	versions := make([]ChartVersion, 0, len(pkg.AvailableVersions))
+	for _, v := range pkg.AvailableVersions {
+		if v.Prerelease {
+			continue
+		}
+		version := ChartVersion{Version: v.Version}
+		if v.Version == pkg.Version {
+			version.AppVersion = pkg.AppVersion
+		}
+		versions = append(versions, version)
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareChartVersions(versions[i].Version, versions[j].Version) > 0
+	})
+	return versions, nil
+}
+
+func getArtifactHub(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, artifactHubAPI+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := artifactHubClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("artifacthub request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("artifacthub returned %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse artifacthub response: %w", err)
+	}
+	return nil
+}
+
+// compareChartVersions compares dotted versions numerically, ignoring a
+// leading "v" and any pre-release suffix
+func compareChartVersions(a, b string) int {
+	pa := strings.Split(strings.SplitN(strings.TrimPrefix(a, "v"), "-", 2)[0], ".")
+	pb := strings.Split(strings.SplitN(strings.TrimPrefix(b, "v"), "-", 2)[0], ".")
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var na, nb int
+		if i < len(pa) {
+			_, _ = fmt.Sscanf(pa[i], "%d", &na)
+		}
+		if i < len(pb) {
+			_, _ = fmt.Sscanf(pb[i], "%d", &nb)
+		}
+		if na != nb {
+			if na > nb {
+				return 1
+			}
+			return -1
+		}
+	}
+	return 0
+}
+
+// FormatChartSearchResults renders ranked results as a numbered table; the
+// numbers are what --install selects
+func FormatChartSearchResults(results []ChartSearchResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%-3s %-40s %-12s %-12s %-12s %-6s %s\n", "#", "CHART", "VERSION", "APP VERSION", "SOURCE", "STARS", "DESCRIPTION"))
+	for i, r := range results {
+		source := r.Source
+		switch {
+		case r.Official:
+			source += "*"
+		case r.VerifiedPublisher:
+			source += "+"
+		}
+		desc := r.Description
+		if r.Deprecated {
+			desc = "[deprecated] " + desc
+		}
+		if len(desc) > 50 {
+			desc = desc[:47] + "..."
+		}
+		sb.WriteString(fmt.Sprintf("%-3d %-40s %-12s %-12s %-12s %-6d %s\n",
+			i+1, r.Ref(), r.Version, r.AppVersion, source, r.Stars, desc))
+	}
+	sb.WriteString("(* official, + verified publisher)\n")
+	return sb.String()
+}
@@ -0,0 +1,137 @@
+package helm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeArtifactHub serves the search and package endpoints
+func fakeArtifactHub(t *testing.T) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/packages/search":
+			if r.URL.Query().Get("ts_query_web") != "postgres" || r.URL.Query().Get("kind") != "0" {
+				t.Errorf("unexpected search query %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"packages": [
+				{"name": "postgres-exporter", "version": "1.0.0", "description": "Exporter", "stars": 5,
+				 "repository": {"name": "community", "url": "https://community.example/charts"}},
+				{"name": "postgresql", "version": "16.4.1", "app_version": "17.2.0", "stars": 800,
+				 "repository": {"name": "bitnami", "url": "https://charts.bitnami.com/bitnami", "verified_publisher": true}},
+				{"name": "postgres", "version": "0.3.0", "app_version": "16.0", "stars": 12,
+				 "repository": {"name": "cloudpirates", "url": "oci://registry-1.docker.io/cloudpirates"}},
+				{"name": "postgres-old", "version": "0.1.0", "deprecated": true, "stars": 400,
+				 "repository": {"name": "legacy", "url": "https://legacy.example"}}
+			]}`))
+		case r.URL.Path == "/packages/helm/bitnami/postgresql":
+			_, _ = w.Write([]byte(`{"version": "16.4.1", "app_version": "17.2.0", "available_versions": [
+				{"version": "16.3.0"}, {"version": "16.4.1"}, {"version": "17.0.0-rc1", "prerelease": true}, {"version": "9.10.2"}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	orig := artifactHubAPI
+	artifactHubAPI = srv.URL
+	t.Cleanup(func() { artifactHubAPI = orig })
+}
+
+func TestDiscoverChartsRanksAndMerges(t *testing.T) {
+	fakeArtifactHub(t)
+	client := &mockHelmClient{runOutput: `[{"name": "bitnami/postgresql", "version": "16.4.0", "app_version": "17.1.0", "description": "PostgreSQL"}]`}
+	m := NewChartManager(client, false)
+
+	results, err := m.DiscoverCharts(context.Background(), "postgres", DiscoverOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var refs []string
+	for _, r := range results {
+		refs = append(refs, r.Ref())
+	}
+	want := []string{
+		"bitnami/postgresql",
+		"oci://registry-1.docker.io/cloudpirates/postgres",
+		"community/postgres-exporter",
+		"legacy/postgres-old",
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("ranked refs = %v, want %v", refs, want)
+	}
+
+	top := results[0]
+	if top.Source != ChartSourceRepo || top.Version != "16.4.0" || top.Stars != 800 || !top.VerifiedPublisher {
+		t.Errorf("configured repo chart should keep its version and gain hub metadata: %+v", top)
+	}
+	if !reflect.DeepEqual(client.runCalls[0], []string{"search", "repo", "postgres", "-o", "json"}) {
+		t.Errorf("helm calls = %v", client.runCalls)
+	}
+}
+
+func TestDiscoverChartsToleratesOneFailingSource(t *testing.T) {
+	fakeArtifactHub(t)
+	m := NewChartManager(&mockHelmClient{runErr: context.DeadlineExceeded}, false)
+
+	results, err := m.DiscoverCharts(context.Background(), "postgres", DiscoverOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ArtifactHub results should be returned when helm fails: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("len(results) = %d, want 2", len(results))
+	}
+
+	if _, err := m.DiscoverCharts(context.Background(), "postgres", DiscoverOptions{SkipArtifactHub: true}); err == nil {
+		t.Error("expected error when the only source fails")
+	}
+}
+
+func TestChartVersionsFromArtifactHub(t *testing.T) {
+	fakeArtifactHub(t)
+	m := NewChartManager(&mockHelmClient{}, false)
+
+	versions, err := m.ChartVersions(context.Background(), ChartSearchResult{ChartInfo: ChartInfo{Name: "postgresql"}, Repo: "bitnami", Source: ChartSourceArtifactHub}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ChartVersion{{Version: "16.4.1", AppVersion: "17.2.0"}, {Version: "16.3.0"}, {Version: "9.10.2"}}
+	if !reflect.DeepEqual(versions, want) {
+		t.Errorf("versions = %+v, want %+v", versions, want)
+	}
+}
+
+func TestInstallPlanForChart(t *testing.T) {
+	m := NewChartManager(&mockHelmClient{}, false)
+
+	known := ChartSearchResult{ChartInfo: ChartInfo{Name: "postgresql", Version: "16.4.1", AppVersion: "17.2.0"}, Repo: "bitnami", Source: ChartSourceArtifactHub}
+	plan := m.InstallPlanForChart(known, "db", "data")
+	if len(plan.Steps) != 3 {
+		t.Fatalf("steps = %+v", plan.Steps)
+	}
+	install := strings.Join(plan.Steps[2].Args, " ")
+	for _, want := range []string{"install db bitnami/postgresql -n data --create-namespace --version 16.4.1", "--set auth.database=app", "--wait"} {
+		if !strings.Contains(install, want) {
+			t.Errorf("install args %q missing %q", install, want)
+		}
+	}
+
+	unknown := ChartSearchResult{ChartInfo: ChartInfo{Name: "pg", Version: "1.2.3"}, Repo: "acme", RepoURL: "https://charts.acme.io", Source: ChartSourceArtifactHub}
+	plan = m.InstallPlanForChart(unknown, "", "")
+	if plan.Steps[0].Args[2] != "acme" || plan.Steps[0].Args[3] != "https://charts.acme.io" {
+		t.Errorf("first step should add the hub repository: %v", plan.Steps[0].Args)
+	}
+	if last := plan.Steps[len(plan.Steps)-1].Args; last[1] != "pg" || last[2] != "acme/pg" {
+		t.Errorf("install args = %v", last)
+	}
+
+	oci := ChartSearchResult{ChartInfo: ChartInfo{Name: "postgres"}, Repo: "cloudpirates", RepoURL: "oci://registry-1.docker.io/cloudpirates", Source: ChartSourceArtifactHub}
+	plan = m.InstallPlanForChart(oci, "", "")
+	if len(plan.Steps) != 1 || plan.Steps[0].Args[2] != "oci://registry-1.docker.io/cloudpirates/postgres" {
+		t.Errorf("OCI charts install directly: %+v", plan.Steps)
+	}
+}
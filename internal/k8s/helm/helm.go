@@ -6,8 +6,12 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("k8s.helm")
+
 // SubAgent handles helm-related queries delegated from the main K8s agent
 type SubAgent struct {
 	client   HelmClient
@@ -42,10 +46,7 @@ type QueryAnalysis struct {
 func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
 	analysis := s.analyzeQuery(query)
 
-	if s.debug {
-		fmt.Printf("[helm] query analysis: type=%s op=%s name=%s ns=%s chart=%s readonly=%v\n",
-			analysis.ResourceType, analysis.Operation, analysis.ResourceName, analysis.Namespace, analysis.ChartName, analysis.IsReadOnly)
-	}
+	logger.Debugf("query analysis: type=%s op=%s name=%s ns=%s chart=%s readonly=%v", analysis.ResourceType, analysis.Operation, analysis.ResourceName, analysis.Namespace, analysis.ChartName, analysis.IsReadOnly)
 
 	// Use namespace from query analysis or options
 	namespace := analysis.Namespace
@@ -149,13 +150,19 @@ func (s *SubAgent) handleReleaseReadOp(ctx context.Context, analysis QueryAnalys
 func (s *SubAgent) handleChartReadOp(ctx context.Context, analysis QueryAnalysis, opts QueryOptions) (*Response, error) {
 	switch analysis.Operation {
 	case "search":
-		charts, err := s.charts.SearchCharts(ctx, analysis.ChartName)
+		// Rank configured repos and ArtifactHub together
+		charts, err := s.charts.DiscoverCharts(ctx, analysis.ChartName, DiscoverOptions{})
 		if err != nil {
 			return nil, err
 		}
+		message := ""
+		if len(charts) > 0 {
+			message = fmt.Sprintf("Seed an install plan with: clanker k8s helm search %s --install 1", analysis.ChartName)
+		}
 		return &Response{
-			Type: ResponseTypeResult,
-			Data: charts,
+			Type:    ResponseTypeResult,
+			Data:    charts,
+			Message: message,
 		}, nil
 
 	case "show", "info":
@@ -232,6 +239,7 @@ func (s *SubAgent) handleReleaseModifyOp(ctx context.Context, query string, anal
 	case "rollback":
 		rollbackOpts := s.parseRollbackFromQuery(query, namespace, analysis.Revision)
 		plan := s.releases.RollbackReleasePlan(rollbackOpts)
+		plan.Warnings = s.releases.RollbackWarnings(ctx, rollbackOpts)
 		return &Response{
 			Type:    ResponseTypePlan,
 			Plan:    plan,
@@ -339,7 +347,7 @@ func (s *SubAgent) detectOperation(query string) string {
 		{"status", []string{"status", "state of"}},
 		{"history", []string{"history", "revisions", "versions of"}},
 		{"values", []string{"values", "configuration of", "config of"}},
-		{"search", []string{"search", "find chart", "look for"}},
+		{"search", []string{"search", "find chart", "find a chart", "find charts", "discover chart", "look for"}},
 		{"show", []string{"show chart", "chart info", "describe chart"}},
 		// Check uninstall before install since "uninstall" contains "install"
 		{"uninstall", []string{"uninstall", "remove release", "delete release"}},
@@ -404,6 +412,7 @@ func (s *SubAgent) extractNamespace(query string) string {
 // extractChartName extracts the chart name from the query
 func (s *SubAgent) extractChartName(query string) string {
 	patterns := []string{
+		`charts?\s+for\s+(?:an?\s+|the\s+)?([a-z0-9][a-z0-9-/]*[a-z0-9])`,
 		`chart\s+([a-z0-9][a-z0-9-/]*[a-z0-9])`,
 		`install\s+[a-z0-9-]+\s+([a-z0-9][a-z0-9-/]*[a-z0-9])`,
 		`search\s+(?:for\s+)?([a-z0-9][a-z0-9-/]*[a-z0-9])`,
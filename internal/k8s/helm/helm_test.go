@@ -90,6 +90,7 @@ func TestDetectOperation(t *testing.T) {
 		{"configuration of app", "values"},
 		{"search for nginx chart", "search"},
 		{"find chart redis", "search"},
+		{"find a chart for postgres", "search"},
 		{"install nginx bitnami/nginx", "install"},
 		{"deploy chart prometheus", "install"},
 		{"upgrade my-release", "upgrade"},
@@ -173,6 +174,7 @@ func TestExtractChartName(t *testing.T) {
 		{"search for chart nginx", "nginx"},
 		{"install myrelease bitnami/redis", "bitnami/redis"},
 		{"show chart prometheus", "prometheus"},
+		{"find a chart for postgres", "postgres"},
 		{"list releases", ""},
 	}
 
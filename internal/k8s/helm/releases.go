@@ -175,27 +175,32 @@ func (m *ReleaseManager) InstallReleasePlan(opts InstallOptions) *HelmPlan {
 	}
 }
 
+// knownRepos maps well-known repository names to their URLs
+var knownRepos = map[string]string{
+	"bitnami":              "https://charts.bitnami.com/bitnami",
+	"stable":               "https://charts.helm.sh/stable",
+	"grafana":              "https://grafana.github.io/helm-charts",
+	"prometheus-community": "https://prometheus-community.github.io/helm-charts",
+	"jenkins":              "https://charts.jenkins.io",
+	"hashicorp":            "https://helm.releases.hashicorp.com",
+	"ingress-nginx":        "https://kubernetes.github.io/ingress-nginx",
+	"jetstack":             "https://charts.jetstack.io",
+	"elastic":              "https://helm.elastic.co",
+	"apache":               "https://charts.apache.org",
+	"traefik":              "https://traefik.github.io/charts",
+	"argo":                 "https://argoproj.github.io/argo-helm",
+	"longhorn":             "https://charts.longhorn.io",
+	"metallb":              "https://metallb.github.io/metallb",
+	"nfs-subdir":           "https://kubernetes-sigs.github.io/nfs-subdir-external-provisioner",
+}
+
+// KnownRepoURL returns the URL of a well-known repository name, or ""
+func KnownRepoURL(name string) string {
+	return knownRepos[name]
+}
+
 // getRepoFromChart extracts the repo name and URL from a chart reference
 func getRepoFromChart(chart string) (string, string) {
-	// Map of known repos to their URLs
-	knownRepos := map[string]string{
-		"bitnami":              "https://charts.bitnami.com/bitnami",
-		"stable":               "https://charts.helm.sh/stable",
-		"grafana":              "https://grafana.github.io/helm-charts",
-		"prometheus-community": "https://prometheus-community.github.io/helm-charts",
-		"jenkins":              "https://charts.jenkins.io",
-		"hashicorp":            "https://helm.releases.hashicorp.com",
-		"ingress-nginx":        "https://kubernetes.github.io/ingress-nginx",
-		"jetstack":             "https://charts.jetstack.io",
-		"elastic":              "https://helm.elastic.co",
-		"apache":               "https://charts.apache.org",
-		"traefik":              "https://traefik.github.io/charts",
-		"argo":                 "https://argoproj.github.io/argo-helm",
-		"longhorn":             "https://charts.longhorn.io",
-		"metallb":              "https://metallb.github.io/metallb",
-		"nfs-subdir":           "https://kubernetes-sigs.github.io/nfs-subdir-external-provisioner",
-	}
-
 	// Check if chart has repo prefix (e.g., "bitnami/redis")
 	if idx := strings.Index(chart, "/"); idx > 0 {
 		repoName := chart[:idx]
@@ -209,6 +214,7 @@ func getRepoFromChart(chart string) (string, string) {
 
 // UpgradeReleasePlan creates a plan for upgrading a Helm release
 func (m *ReleaseManager) UpgradeReleasePlan(opts UpgradeOptions) *HelmPlan {
+	opts.Wait, opts.Timeout = releaseWaitDefaults(opts.Wait, opts.Timeout, opts.DryRun)
 	steps := []HelmStep{}
 
 	// Add repo setup steps if chart is from a known repo
@@ -319,6 +325,7 @@ func (m *ReleaseManager) UpgradeReleasePlan(opts UpgradeOptions) *HelmPlan {
 
 // RollbackReleasePlan creates a plan for rolling back a Helm release
 func (m *ReleaseManager) RollbackReleasePlan(opts RollbackOptions) *HelmPlan {
+	opts.Wait, opts.Timeout = releaseWaitDefaults(opts.Wait, opts.Timeout, opts.DryRun)
 	args := []string{"rollback", opts.ReleaseName}
 
 	if opts.Revision > 0 {
@@ -370,6 +377,18 @@ func (m *ReleaseManager) RollbackReleasePlan(opts RollbackOptions) *HelmPlan {
 	}
 }
 
+// releaseWaitDefaults makes upgrades and rollbacks wait for the release to
+// become ready, with a bounded timeout, unless they are dry runs
+func releaseWaitDefaults(wait bool, timeout time.Duration, dryRun bool) (bool, time.Duration) {
+	if dryRun {
+		return wait, timeout
+	}
+	if timeout <= 0 {
+		timeout = defaultReleaseTimeout
+	}
+	return true, timeout
+}
+
 // UninstallReleasePlan creates a plan for uninstalling a Helm release
 func (m *ReleaseManager) UninstallReleasePlan(opts UninstallOptions) *HelmPlan {
 	args := []string{"uninstall", opts.ReleaseName}
@@ -441,9 +460,7 @@ func (m *ReleaseManager) parseReleaseList(data []byte) ([]ReleaseInfo, error) {
 	for _, item := range rawList {
 		release, err := m.parseReleaseJSON(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[helm] failed to parse release: %v\n", err)
-			}
+			logger.Debugf("failed to parse release: %v", err)
 			continue
 		}
 		releases = append(releases, *release)
@@ -0,0 +1,165 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+)
+
+// defaultReleaseTimeout bounds --wait on upgrades and rollbacks when no
+// timeout was given, so a stuck release fails the plan instead of hanging
+const defaultReleaseTimeout = 5 * time.Minute
+
+// chartRefPattern splits a history chart field such as
+// "kube-prometheus-stack-55.5.0" into name and version
+var chartRefPattern = regexp.MustCompile(`^(.+?)-(v?\d+\.\d+.*)$`)
+
+// RollbackWarnings inspects the release history before a rollback and
+// reports what the rollback will cross: chart or major version changes, CRD
+// changes, a target revision that itself failed, or one that no longer
+// exists. Lookup failures become warnings too, since an unchecked rollback
+// deserves a second look.
+func (m *ReleaseManager) RollbackWarnings(ctx context.Context, opts RollbackOptions) []string {
+	if opts.ReleaseName == "" {
+		return nil
+	}
+	history, err := m.GetReleaseHistory(ctx, opts.ReleaseName, opts.Namespace)
+	if err != nil {
+		return []string{fmt.Sprintf("Could not check the history of %s before rollback: %v", opts.ReleaseName, err)}
+	}
+
+	current, target, warnings := rollbackHistoryWarnings(history, opts.Revision)
+	if current == nil || target == nil {
+		return warnings
+	}
+
+	currentManifest, err := m.revisionManifest(ctx, opts.ReleaseName, opts.Namespace, current.Revision)
+	if err == nil {
+		var targetManifest string
+		targetManifest, err = m.revisionManifest(ctx, opts.ReleaseName, opts.Namespace, target.Revision)
+		if err == nil {
+			warnings = append(warnings, crdChangeWarnings(currentManifest, targetManifest, target.Revision)...)
+		}
+	}
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("Could not compare CRDs between revisions %d and %d: %v", current.Revision, target.Revision, err))
+	}
+	return warnings
+}
+
+func (m *ReleaseManager) revisionManifest(ctx context.Context, name, namespace string, revision int) (string, error) {
+	return m.client.RunWithNamespace(ctx, namespace, "get", "manifest", name, "--revision", fmt.Sprintf("%d", revision))
+}
+
+// rollbackHistoryWarnings finds the deployed and target revisions (0 means
+// the one before the deployed revision, as helm rollback does) and compares
+// their charts
+func rollbackHistoryWarnings(history []ReleaseHistoryEntry, revision int) (current, target *ReleaseHistoryEntry, warnings []string) {
+	if len(history) == 0 {
+		return nil, nil, []string{"Release has no history; nothing to roll back to"}
+	}
+
+	// The deployed revision, or the newest one when none is deployed
+	latest := &history[0]
+	for i := range history {
+		entry := &history[i]
+		if entry.Revision > latest.Revision {
+			latest = entry
+		}
+		if entry.Status == string(StatusDeployed) && (current == nil || entry.Revision > current.Revision) {
+			current = entry
+		}
+	}
+	if current == nil {
+		current = latest
+	}
+
+	if revision <= 0 {
+		revision = current.Revision - 1
+	}
+	for i := range history {
+		if history[i].Revision == revision {
+			target = &history[i]
+		}
+	}
+	if target == nil {
+		return current, nil, []string{fmt.Sprintf("Revision %d is not in the release history (it may have been pruned by --history-max); the rollback will fail", revision)}
+	}
+	if target.Revision == current.Revision {
+		return current, target, []string{fmt.Sprintf("Revision %d is already the deployed revision", revision)}
+	}
+
+	currentName, currentVersion := splitChartRef(current.Chart)
+	targetName, targetVersion := splitChartRef(target.Chart)
+	switch {
+	case currentName != targetName:
+		warnings = append(warnings, fmt.Sprintf("Revision %d used a different chart (%s) than the deployed revision %d (%s)", target.Revision, target.Chart, current.Revision, current.Chart))
+	case chartMajor(currentVersion) != chartMajor(targetVersion):
+		warnings = append(warnings, fmt.Sprintf("Rollback crosses a chart major version (%s -> %s); values and resource layouts may be incompatible", currentVersion, targetVersion))
+	}
+	if current.AppVersion != "" && target.AppVersion != "" && current.AppVersion != target.AppVersion {
+		warnings = append(warnings, fmt.Sprintf("App version goes back from %s to %s; check for data or schema migrations that cannot be undone", current.AppVersion, target.AppVersion))
+	}
+	if target.Status == string(StatusFailed) {
+		warnings = append(warnings, fmt.Sprintf("Revision %d is recorded as failed; rolling back to it may fail again", target.Revision))
+	}
+	return current, target, warnings
+}
+
+// crdChangeWarnings compares the CustomResourceDefinitions rendered in two
+// release manifests. CRDs installed from a chart's crds/ directory are not
+// part of the manifest and are never touched by helm rollback.
+func crdChangeWarnings(currentManifest, targetManifest string, targetRevision int) []string {
+	current := manifestCRDs(currentManifest)
+	target := manifestCRDs(targetManifest)
+
+	var warnings []string
+	for name, doc := range current {
+		targetDoc, ok := target[name]
+		switch {
+		case !ok:
+			warnings = append(warnings, fmt.Sprintf("CRD %s is not in revision %d; the rollback deletes it and every custom resource of that type", name, targetRevision))
+		case targetDoc != doc:
+			warnings = append(warnings, fmt.Sprintf("CRD %s differs in revision %d; existing custom resources may not validate against the older schema", name, targetRevision))
+		}
+	}
+	for name := range target {
+		if _, ok := current[name]; !ok {
+			warnings = append(warnings, fmt.Sprintf("CRD %s is recreated by the rollback to revision %d", name, targetRevision))
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
+
+func manifestCRDs(manifest string) map[string]string {
+	crds := map[string]string{}
+	objects, err := plan.SplitManifests(manifest, "")
+	if err != nil {
+		return crds
+	}
+	for _, obj := range objects {
+		if obj.Kind == "CustomResourceDefinition" {
+			crds[obj.Name] = strings.TrimSpace(obj.Content)
+		}
+	}
+	return crds
+}
+
+// splitChartRef splits "name-version" from helm history
+func splitChartRef(chart string) (name, version string) {
+	if m := chartRefPattern.FindStringSubmatch(chart); m != nil {
+		return m[1], m[2]
+	}
+	return chart, ""
+}
+
+func chartMajor(version string) string {
+	major, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+	return major
+}
@@ -0,0 +1,121 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedHelmClient answers by the joined args, so one test can serve
+// history and per-revision manifests
+type scriptedHelmClient struct {
+	outputs map[string]string
+}
+
+func (c *scriptedHelmClient) Run(ctx context.Context, args ...string) (string, error) {
+	return c.RunWithNamespace(ctx, "", args...)
+}
+
+func (c *scriptedHelmClient) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	key := strings.Join(args, " ")
+	if out, ok := c.outputs[key]; ok {
+		return out, nil
+	}
+	return "", fmt.Errorf("unexpected helm call: %s", key)
+}
+
+const crdV1 = `---
+# Source: operator/templates/crd.yaml
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  versions:
+  - name: v1
+`
+
+func TestRollbackWarnings(t *testing.T) {
+	client := &scriptedHelmClient{outputs: map[string]string{
+		"history operator -o json": `[
+			{"revision": 1, "status": "superseded", "chart": "operator-1.4.0", "app_version": "1.4"},
+			{"revision": 2, "status": "failed", "chart": "operator-2.0.0", "app_version": "2.0"},
+			{"revision": 3, "status": "deployed", "chart": "operator-2.1.0", "app_version": "2.1"}
+		]`,
+		"get manifest operator --revision 3": crdV1 + `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gadgets.example.com
+`,
+		"get manifest operator --revision 1": strings.Replace(crdV1, "name: v1", "name: v1beta1", 1),
+		"get manifest operator --revision 2": crdV1,
+	}}
+	m := NewReleaseManager(client, false)
+
+	warnings := m.RollbackWarnings(context.Background(), RollbackOptions{ReleaseName: "operator", Revision: 1, Namespace: "ops"})
+	joined := strings.Join(warnings, "\n")
+	for _, want := range []string{
+		"crosses a chart major version (2.1.0 -> 1.4.0)",
+		"App version goes back from 2.1 to 1.4",
+		"CRD gadgets.example.com is not in revision 1; the rollback deletes it",
+		"CRD widgets.example.com differs in revision 1",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("warnings missing %q:\n%s", want, joined)
+		}
+	}
+
+	// Revision 0 means the one before the deployed revision
+	warnings = m.RollbackWarnings(context.Background(), RollbackOptions{ReleaseName: "operator", Namespace: "ops"})
+	joined = strings.Join(warnings, "\n")
+	if !strings.Contains(joined, "Revision 2 is recorded as failed") || strings.Contains(joined, "major version") {
+		t.Errorf("previous-revision warnings:\n%s", joined)
+	}
+
+	warnings = m.RollbackWarnings(context.Background(), RollbackOptions{ReleaseName: "operator", Revision: 7, Namespace: "ops"})
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "Revision 7 is not in the release history") {
+		t.Errorf("missing revision warnings = %v", warnings)
+	}
+}
+
+func TestRollbackWarningsWhenHistoryUnavailable(t *testing.T) {
+	m := NewReleaseManager(&scriptedHelmClient{}, false)
+	warnings := m.RollbackWarnings(context.Background(), RollbackOptions{ReleaseName: "web"})
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "Could not check the history of web") {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
+
+func TestSplitChartRef(t *testing.T) {
+	tests := []struct{ chart, name, version string }{
+		{"kube-prometheus-stack-55.5.0", "kube-prometheus-stack", "55.5.0"},
+		{"postgresql-16.4.1", "postgresql", "16.4.1"},
+		{"app-v2.0.0-rc1", "app", "v2.0.0-rc1"},
+		{"local", "local", ""},
+	}
+	for _, tt := range tests {
+		if name, version := splitChartRef(tt.chart); name != tt.name || version != tt.version {
+			t.Errorf("splitChartRef(%q) = %q, %q", tt.chart, name, version)
+		}
+	}
+}
+
+func TestUpgradeAndRollbackWaitByDefault(t *testing.T) {
+	m := NewReleaseManager(&mockHelmClient{}, false)
+
+	rollback := strings.Join(m.RollbackReleasePlan(RollbackOptions{ReleaseName: "web", Revision: 2}).Steps[0].Args, " ")
+	if !strings.Contains(rollback, "--wait --timeout 5m0s") {
+		t.Errorf("rollback args = %s", rollback)
+	}
+	upgrade := m.UpgradeReleasePlan(UpgradeOptions{ReleaseName: "web", Chart: "./chart", Timeout: 10 * time.Minute})
+	if args := strings.Join(upgrade.Steps[len(upgrade.Steps)-1].Args, " "); !strings.Contains(args, "--wait --timeout 10m0s") {
+		t.Errorf("upgrade args = %s", args)
+	}
+	dryRun := strings.Join(m.RollbackReleasePlan(RollbackOptions{ReleaseName: "web", DryRun: true}).Steps[0].Args, " ")
+	if strings.Contains(dryRun, "--wait") {
+		t.Errorf("dry runs should not wait: %s", dryRun)
+	}
+}
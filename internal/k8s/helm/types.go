@@ -2,6 +2,7 @@ package helm
 
 import (
 	"context"
+	"strings"
 	"time"
 )
 
@@ -51,6 +52,7 @@ type HelmPlan struct {
 	Summary   string     `json:"summary"`
 	Steps     []HelmStep `json:"steps"`
 	Notes     []string   `json:"notes,omitempty"`
+	Warnings  []string   `json:"warnings,omitempty"`
 }
 
 // HelmStep represents a single step in a helm plan
@@ -109,6 +111,40 @@ type ChartInfo struct {
 	Deprecated  bool     `json:"deprecated,omitempty"`
 }
 
+// ChartSearchResult is a chart found by discovery, from a configured
+// repository or ArtifactHub, with the signals used to rank it
+type ChartSearchResult struct {
+	ChartInfo
+	Repo              string `json:"repo"`
+	RepoURL           string `json:"repoUrl,omitempty"`
+	Source            string `json:"source"`
+	Stars             int    `json:"stars,omitempty"`
+	Official          bool   `json:"official,omitempty"`
+	VerifiedPublisher bool   `json:"verifiedPublisher,omitempty"`
+	Score             int    `json:"score"`
+}
+
+// Ref returns the chart reference helm install takes: repo/name, or the
+// full URL for OCI registries
+func (r ChartSearchResult) Ref() string {
+	if r.IsOCI() {
+		return strings.TrimSuffix(r.RepoURL, "/") + "/" + r.Name
+	}
+	return r.Repo + "/" + r.Name
+}
+
+// IsOCI reports whether the chart lives in an OCI registry, which helm
+// installs from directly without a repo add
+func (r ChartSearchResult) IsOCI() bool {
+	return strings.HasPrefix(r.RepoURL, "oci://")
+}
+
+// ChartVersion is one published version of a chart
+type ChartVersion struct {
+	Version    string `json:"version"`
+	AppVersion string `json:"appVersion,omitempty"`
+}
+
 // RepoInfo contains Helm repository information
 type RepoInfo struct {
 	Name string `json:"name"`
@@ -0,0 +1,36 @@
+package k8s
+
+import (
+	"context"
+
+	"github.com/bgdnvk/clanker/internal/k8s/imagescan"
+)
+
+// newImageScanner is replaced in tests
+var newImageScanner = imagescan.NewScanner
+
+// PlanImages returns the container images a plan's manifests deploy
+func PlanImages(plan *K8sPlan) []string {
+	manifests := make([]string, 0, len(plan.Manifests))
+	for _, m := range plan.Manifests {
+		manifests = append(manifests, m.Content)
+	}
+	return imagescan.ManifestImages(manifests...)
+}
+
+// previewImageScans scans the images a freshly generated plan deploys and
+// warns about those an apply would refuse
+func (a *Agent) previewImageScans(ctx context.Context, plan *K8sPlan) {
+	if plan == nil || !imagescan.Enabled() {
+		return
+	}
+	images := PlanImages(plan)
+	if len(images) == 0 {
+		return
+	}
+	plan.ImageScans = newImageScanner().ScanAll(ctx, images)
+	if warnings := imagescan.Warnings(plan.ImageScans, imagescan.MaxCritical()); len(warnings) > 0 {
+		plan.Warnings = append(plan.Warnings, warnings...)
+		plan.Warnings = append(plan.Warnings, "apply is blocked unless --allow-vulnerable is given")
+	}
+}
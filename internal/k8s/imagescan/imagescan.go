@@ -0,0 +1,227 @@
+// Package imagescan checks container images for known vulnerabilities
+// before clanker deploys them. Images are scanned with trivy; when trivy is
+// not installed the scan is skipped rather than blocking the plan.
+//
+// The gate is configured under kubernetes.image_scan:
+//
+//	kubernetes:
+//	  image_scan:
+//	    enabled: true
+//	    max_critical: 0 # critical CVEs allowed per image before apply is blocked
+package imagescan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Severities trivy reports
+const (
+	SeverityCritical = "CRITICAL"
+	SeverityHigh     = "HIGH"
+)
+
+// scanTimeout bounds one trivy run, which may pull the image database
+const scanTimeout = 5 * time.Minute
+
+// Vulnerability is one CVE found in an image
+type Vulnerability struct {
+	ID           string `json:"id"`
+	Package      string `json:"package"`
+	Installed    string `json:"installed,omitempty"`
+	FixedVersion string `json:"fixed_version,omitempty"`
+	Severity     string `json:"severity"`
+}
+
+// Result is the scan outcome for one image
+type Result struct {
+	Image    string          `json:"image"`
+	Critical int             `json:"critical"`
+	High     int             `json:"high"`
+	CVEs     []Vulnerability `json:"cves,omitempty"`
+	Skipped  string          `json:"skipped,omitempty"` // why the image was not scanned
+}
+
+// String summarizes the result on one line
+func (r Result) String() string {
+	if r.Skipped != "" {
+		return fmt.Sprintf("%s: scan skipped (%s)", r.Image, r.Skipped)
+	}
+	return fmt.Sprintf("%s: %d critical, %d high", r.Image, r.Critical, r.High)
+}
+
+// Runner executes trivy with the given arguments and returns its stdout
+type Runner func(ctx context.Context, args ...string) ([]byte, error)
+
+// Scanner runs image scans
+type Scanner struct {
+	run Runner
+}
+
+// NewScanner returns a scanner backed by the trivy CLI
+func NewScanner() *Scanner {
+	return &Scanner{run: runTrivy}
+}
+
+// NewScannerWithRunner returns a scanner that runs trivy through run
+func NewScannerWithRunner(run Runner) *Scanner {
+	return &Scanner{run: run}
+}
+
+// errNoTrivy marks a missing trivy binary so the scan is skipped
+var errNoTrivy = fmt.Errorf("trivy not installed")
+
+func runTrivy(ctx context.Context, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath("trivy"); err != nil {
+		return nil, errNoTrivy
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "trivy", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// trivyReport is the subset of trivy's JSON output clanker reads
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID  string `json:"VulnerabilityID"`
+			PkgName          string `json:"PkgName"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// Scan scans one image for critical and high vulnerabilities
+func (s *Scanner) Scan(ctx context.Context, image string) Result {
+	result := Result{Image: image}
+	ctx, cancel := context.WithTimeout(ctx, scanTimeout)
+	defer cancel()
+
+	out, err := s.run(ctx, "image", "--quiet", "--format", "json", "--scanners", "vuln",
+		"--severity", SeverityCritical+","+SeverityHigh, "--timeout", scanTimeout.String(), image)
+	if err != nil {
+		result.Skipped = err.Error()
+		return result
+	}
+
+	var report trivyReport
+	if err := json.Unmarshal(out, &report); err != nil {
+		result.Skipped = fmt.Sprintf("unreadable trivy output: %v", err)
+		return result
+	}
+
+	seen := make(map[string]bool)
+	for _, target := range report.Results {
+		for _, v := range target.Vulnerabilities {
+			key := v.VulnerabilityID + "/" + v.PkgName
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			severity := strings.ToUpper(v.Severity)
+			switch severity {
+			case SeverityCritical:
+				result.Critical++
+			case SeverityHigh:
+				result.High++
+			default:
+				continue
+			}
+			result.CVEs = append(result.CVEs, Vulnerability{
+				ID:           v.VulnerabilityID,
+				Package:      v.PkgName,
+				Installed:    v.InstalledVersion,
+				FixedVersion: v.FixedVersion,
+				Severity:     severity,
+			})
+		}
+	}
+	sort.SliceStable(result.CVEs, func(i, j int) bool {
+		if result.CVEs[i].Severity != result.CVEs[j].Severity {
+			return result.CVEs[i].Severity == SeverityCritical
+		}
+		return result.CVEs[i].ID < result.CVEs[j].ID
+	})
+	return result
+}
+
+// ScanAll scans each distinct image once, in order
+func (s *Scanner) ScanAll(ctx context.Context, images []string) []Result {
+	var results []Result
+	seen := make(map[string]bool)
+	for _, image := range images {
+		if image == "" || seen[image] {
+			continue
+		}
+		seen[image] = true
+		results = append(results, s.Scan(ctx, image))
+	}
+	return results
+}
+
+// Enabled reports whether deploy plans are scanned
+func Enabled() bool {
+	if !viper.IsSet("kubernetes.image_scan.enabled") {
+		return true
+	}
+	return viper.GetBool("kubernetes.image_scan.enabled")
+}
+
+// MaxCritical returns how many critical CVEs an image may carry before
+// apply is blocked
+func MaxCritical() int {
+	if n := viper.GetInt("kubernetes.image_scan.max_critical"); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// Blocked returns the scanned images whose critical count is over the limit
+func Blocked(results []Result, maxCritical int) []Result {
+	var blocked []Result
+	for _, r := range results {
+		if r.Skipped == "" && r.Critical > maxCritical {
+			blocked = append(blocked, r)
+		}
+	}
+	return blocked
+}
+
+// Warnings describes each over-limit image, naming its first critical CVEs
+func Warnings(results []Result, maxCritical int) []string {
+	var warnings []string
+	for _, r := range Blocked(results, maxCritical) {
+		var ids []string
+		for _, v := range r.CVEs {
+			if v.Severity != SeverityCritical {
+				break
+			}
+			if len(ids) == 3 {
+				ids = append(ids, "...")
+				break
+			}
+			ids = append(ids, v.ID)
+		}
+		warnings = append(warnings, fmt.Sprintf("image %s has %d critical vulnerabilities (limit %d): %s",
+			r.Image, r.Critical, maxCritical, strings.Join(ids, ", ")))
+	}
+	return warnings
+}
@@ -0,0 +1,142 @@
+package imagescan
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const trivyOutput = `{
+  "Results": [
+    {"Target": "app:1 (debian 12)", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2024-0002", "PkgName": "openssl", "InstalledVersion": "3.0.1", "FixedVersion": "3.0.2", "Severity": "HIGH"},
+      {"VulnerabilityID": "CVE-2024-0001", "PkgName": "glibc", "InstalledVersion": "2.36", "Severity": "CRITICAL"},
+      {"VulnerabilityID": "CVE-2024-0001", "PkgName": "glibc", "InstalledVersion": "2.36", "Severity": "CRITICAL"}
+    ]},
+    {"Target": "app/go.mod", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-2024-0003", "PkgName": "golang.org/x/net", "Severity": "CRITICAL"},
+      {"VulnerabilityID": "CVE-2024-0004", "PkgName": "golang.org/x/text", "Severity": "MEDIUM"}
+    ]}
+  ]
+}`
+
+func fakeScanner(out string, err error) *Scanner {
+	return &Scanner{run: func(ctx context.Context, args ...string) ([]byte, error) {
+		return []byte(out), err
+	}}
+}
+
+func TestScanCountsAndDedupes(t *testing.T) {
+	result := fakeScanner(trivyOutput, nil).Scan(context.Background(), "app:1")
+	if result.Skipped != "" {
+		t.Fatalf("unexpected skip: %s", result.Skipped)
+	}
+	if result.Critical != 2 || result.High != 1 {
+		t.Fatalf("counts = %d critical, %d high, want 2, 1", result.Critical, result.High)
+	}
+	var ids []string
+	for _, v := range result.CVEs {
+		ids = append(ids, v.ID)
+	}
+	want := []string{"CVE-2024-0001", "CVE-2024-0003", "CVE-2024-0002"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("CVEs = %v, want %v", ids, want)
+	}
+}
+
+func TestScanSkipsWhenTrivyFails(t *testing.T) {
+	result := fakeScanner("", errNoTrivy).Scan(context.Background(), "app:1")
+	if result.Skipped != "trivy not installed" {
+		t.Fatalf("Skipped = %q", result.Skipped)
+	}
+	if blocked := Blocked([]Result{result}, 0); len(blocked) != 0 {
+		t.Fatalf("skipped scan should not block: %v", blocked)
+	}
+
+	result = fakeScanner("not json", nil).Scan(context.Background(), "app:1")
+	if !strings.Contains(result.Skipped, "unreadable trivy output") {
+		t.Fatalf("Skipped = %q", result.Skipped)
+	}
+}
+
+func TestScanAllScansEachImageOnce(t *testing.T) {
+	var scanned []string
+	s := &Scanner{run: func(ctx context.Context, args ...string) ([]byte, error) {
+		scanned = append(scanned, args[len(args)-1])
+		return nil, errors.New("offline")
+	}}
+	results := s.ScanAll(context.Background(), []string{"a:1", "b:1", "a:1", ""})
+	if len(results) != 2 || !reflect.DeepEqual(scanned, []string{"a:1", "b:1"}) {
+		t.Fatalf("scanned %v, results %v", scanned, results)
+	}
+}
+
+func TestBlockedAndWarnings(t *testing.T) {
+	results := []Result{
+		{Image: "clean:1"},
+		{Image: "bad:1", Critical: 2, CVEs: []Vulnerability{
+			{ID: "CVE-1", Severity: SeverityCritical},
+			{ID: "CVE-2", Severity: SeverityCritical},
+			{ID: "CVE-3", Severity: SeverityHigh},
+		}},
+	}
+	if blocked := Blocked(results, 2); len(blocked) != 0 {
+		t.Fatalf("limit 2 should allow 2 critical: %v", blocked)
+	}
+	warnings := Warnings(results, 1)
+	if len(warnings) != 1 {
+		t.Fatalf("warnings = %v", warnings)
+	}
+	if !strings.Contains(warnings[0], "bad:1 has 2 critical") || !strings.Contains(warnings[0], "CVE-1, CVE-2") || strings.Contains(warnings[0], "CVE-3") {
+		t.Fatalf("warning = %q", warnings[0])
+	}
+}
+
+func TestManifestImages(t *testing.T) {
+	manifest := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+        - name: migrate
+          image: web-migrate:1
+      containers:
+        - name: web
+          image: web:1
+        - name: proxy
+          image: envoy:1
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - port: 80
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+spec:
+  schedule: "0 2 * * *"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+            - name: report
+              image: web:1
+            - name: upload
+              image: uploader:2
+`
+	want := []string{"web-migrate:1", "web:1", "envoy:1", "uploader:2"}
+	if got := ManifestImages(manifest); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ManifestImages = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,65 @@
+package imagescan
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// podSpecPaths are where pod specs sit in the workload kinds clanker applies
+var podSpecPaths = [][]string{
+	{"spec"},                     // Pod
+	{"spec", "template", "spec"}, // Deployment, StatefulSet, DaemonSet, Job
+	{"spec", "jobTemplate", "spec", "template", "spec"}, // CronJob
+}
+
+// ManifestImages returns the container images referenced by multi-document
+// YAML manifests, in order of first appearance
+func ManifestImages(manifests ...string) []string {
+	var images []string
+	seen := make(map[string]bool)
+	for _, manifest := range manifests {
+		images = appendImages(images, seen, manifest)
+	}
+	return images
+}
+
+func appendImages(images []string, seen map[string]bool, manifest string) []string {
+	dec := yaml.NewDecoder(bytes.NewReader([]byte(manifest)))
+	for {
+		var doc map[string]any
+		if err := dec.Decode(&doc); err != nil {
+			break // io.EOF, or a document that is not YAML
+		}
+		for _, path := range podSpecPaths {
+			spec, ok := lookup(doc, path)
+			if !ok {
+				continue
+			}
+			for _, field := range []string{"initContainers", "containers"} {
+				containers, _ := spec[field].([]any)
+				for _, c := range containers {
+					container, _ := c.(map[string]any)
+					image, _ := container["image"].(string)
+					if image != "" && !seen[image] {
+						seen[image] = true
+						images = append(images, image)
+					}
+				}
+			}
+		}
+	}
+	return images
+}
+
+func lookup(doc map[string]any, path []string) (map[string]any, bool) {
+	current := doc
+	for _, key := range path {
+		next, ok := current[key].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return current, true
+}
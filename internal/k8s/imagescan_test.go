@@ -0,0 +1,35 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s/imagescan"
+)
+
+func TestPreviewImageScansWarns(t *testing.T) {
+	orig := newImageScanner
+	defer func() { newImageScanner = orig }()
+	var scanned []string
+	newImageScanner = func() *imagescan.Scanner {
+		return imagescan.NewScannerWithRunner(func(ctx context.Context, args ...string) ([]byte, error) {
+			scanned = append(scanned, args[len(args)-1])
+			return []byte(`{"Results":[{"Vulnerabilities":[{"VulnerabilityID":"CVE-2024-1","PkgName":"openssl","Severity":"CRITICAL"}]}]}`), nil
+		})
+	}
+
+	plan := &K8sPlan{Manifests: []Manifest{{Kind: "Deployment", Name: "web", Content: validDeployment}}}
+	(&Agent{}).previewImageScans(context.Background(), plan)
+
+	if len(scanned) != 1 || scanned[0] != "nginx" {
+		t.Fatalf("scanned %v, want [nginx]", scanned)
+	}
+	if len(plan.ImageScans) != 1 || plan.ImageScans[0].Critical != 1 {
+		t.Fatalf("ImageScans = %+v", plan.ImageScans)
+	}
+	warnings := strings.Join(plan.Warnings, "\n")
+	if !strings.Contains(warnings, "CVE-2024-1") || !strings.Contains(warnings, "--allow-vulnerable") {
+		t.Fatalf("warnings = %q", warnings)
+	}
+}
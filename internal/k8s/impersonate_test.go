@@ -0,0 +1,60 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseImpersonation(t *testing.T) {
+	identity, err := ParseImpersonation(" sa:apps/deployer ", []string{"deployers", " "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Impersonation{User: "system:serviceaccount:apps:deployer", Groups: []string{"deployers"}}
+	if !reflect.DeepEqual(identity, want) {
+		t.Errorf("identity = %+v, want %+v", identity, want)
+	}
+
+	if identity, err := ParseImpersonation("", nil); identity != nil || err != nil {
+		t.Errorf("empty = %+v, %v", identity, err)
+	}
+	if _, err := ParseImpersonation("", []string{"deployers"}); err == nil {
+		t.Error("expected error for --as-group without --as")
+	}
+	if _, err := ParseImpersonation("sa:apps", nil); err == nil {
+		t.Error("expected error for service account without a name")
+	}
+}
+
+func TestImpersonationCommandArgs(t *testing.T) {
+	identity := &Impersonation{User: "jane", Groups: []string{"dev"}}
+
+	if got := identity.CommandArgs("kubectl", []string{"apply", "-f", "-"}); !reflect.DeepEqual(got, []string{"--as=jane", "--as-group=dev", "apply", "-f", "-"}) {
+		t.Errorf("kubectl args = %v", got)
+	}
+	if got := identity.CommandArgs("helm", []string{"upgrade", "--install", "web", "bitnami/nginx"}); !reflect.DeepEqual(got, []string{"--kube-as-user=jane", "--kube-as-group=dev", "upgrade", "--install", "web", "bitnami/nginx"}) {
+		t.Errorf("helm args = %v", got)
+	}
+	if got := identity.CommandArgs("eksctl", []string{"create", "cluster"}); !reflect.DeepEqual(got, []string{"create", "cluster"}) {
+		t.Errorf("eksctl args = %v", got)
+	}
+	var none *Impersonation
+	if got := none.CommandArgs("kubectl", []string{"get", "pods"}); !reflect.DeepEqual(got, []string{"get", "pods"}) {
+		t.Errorf("nil identity args = %v", got)
+	}
+}
+
+func TestClientImpersonationFlags(t *testing.T) {
+	client := NewClient("/tmp/kubeconfig", "prod", false)
+	client.SetImpersonation(&Impersonation{User: "jane"})
+
+	if got := client.buildArgs("web", []string{"get", "pods"}); !reflect.DeepEqual(got, []string{"--kubeconfig", "/tmp/kubeconfig", "--context", "prod", "--as=jane", "-n", "web", "get", "pods"}) {
+		t.Errorf("kubectl args = %v", got)
+	}
+	if got := client.buildHelmArgs("web", []string{"list"}); !reflect.DeepEqual(got, []string{"--kubeconfig", "/tmp/kubeconfig", "--kube-context", "prod", "--kube-as-user=jane", "-n", "web", "list"}) {
+		t.Errorf("helm args = %v", got)
+	}
+	if client.nativeBackend() != nil {
+		t.Error("the client-go backend must not be used while impersonating")
+	}
+}
@@ -0,0 +1,296 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s/backup"
+	"github.com/bgdnvk/clanker/internal/k8s/networking"
+	"github.com/bgdnvk/clanker/internal/k8s/openshift"
+	"github.com/bgdnvk/clanker/internal/k8s/storage"
+	"github.com/spf13/viper"
+)
+
+// defaultIntentThreshold is the rule confidence below which the model
+// picks the category, when the agent has one. A question one rule matches
+// scores above 0.9; two competing rules land near 0.5.
+const defaultIntentThreshold = 0.6
+
+// intentCategories describe each category to the model
+var intentCategories = map[string]string{
+	"workload_identity":    "grant a service account access to cloud storage (IRSA, Workload Identity)",
+	"cluster_provisioning": "create or set up a new cluster",
+	"cluster_addon":        "install, upgrade or remove an EKS add-on",
+	"cluster_upgrade":      "upgrade the cluster's Kubernetes version",
+	"node_patching":        "patch node operating systems and reboot or replace nodes one at a time",
+	"cluster_scaling":      "add, remove or scale nodes",
+	"rbac":                 "who can do what: roles, bindings and permissions",
+	"openshift":            "OpenShift routes, DeploymentConfigs, projects and SCCs",
+	"backup":               "install Velero, back up or restore namespaces, or list backups",
+	"workloads":            "list, describe, scale, restart or change pods, deployments, statefulsets, daemonsets and jobs",
+	"networking":           "list or change services, ingresses, endpoints and network policies, or check whether a pod can reach a service",
+	"storage":              "list or change volumes, PVCs, configmaps and secrets, expand, snapshot or restore PVCs, or explain why a PVC is pending",
+	"helm":                 "helm charts and releases",
+	"telemetry":            "CPU and memory usage, metrics, capacity and bin-packing",
+	"sre":                  "troubleshoot: why something is broken, failing, not ready or misbehaving, and how to fix it",
+	"general":              "anything else about the cluster",
+}
+
+// intentRule is one category signal, scored like routing.Rule; routing
+// cannot be imported here without a cycle through the AI client
+type intentRule struct {
+	Category string
+	Weight   float64
+	Reason   string
+	// Match returns the evidence found in the lowercased question, or nil
+	Match func(questionLower string) []string
+}
+
+// IntentCandidate is a category ranked for a question
+type IntentCandidate struct {
+	Category   string   `json:"category"`
+	Confidence float64  `json:"confidence"`
+	Reason     string   `json:"reason"`
+	Signals    []string `json:"signals,omitempty"`
+
+	score float64
+}
+
+// maxIntentBonus caps how much repeated evidence adds to a rule's weight;
+// rules are spaced further apart than this
+const maxIntentBonus = 4
+
+// intentRules score a lowercased question for each category. Weights keep
+// the precedence of the checks they replace, except that troubleshooting
+// phrasing outranks the resource a question mentions: "why are my
+// services not getting endpoints" is an SRE question about services.
+func intentRules() []intentRule {
+	return []intentRule{
+		{Category: "workload_identity", Weight: 100, Reason: "service account access to cloud storage",
+			Match: matched(func(q string) bool { _, ok := ParseWorkloadIdentityQuery(q); return ok }, "workload identity request")},
+		{Category: "cluster_provisioning", Weight: 95, Reason: "cluster creation",
+			Match: matched(func(q string) bool {
+				return strings.Contains(q, "cluster") && containsAny(q, []string{"create", "provision", "setup"})
+			}, "cluster create intent")},
+		{Category: "cluster_addon", Weight: 90, Reason: "EKS add-on change",
+			Match: matched(func(q string) bool { _, ok := ParseAddonQuery(q); return ok }, "add-on request")},
+		{Category: "cluster_upgrade", Weight: 85, Reason: "cluster version upgrade",
+			Match: matched(func(q string) bool { _, ok := ParseUpgradeQuery(q); return ok }, "upgrade request")},
+		{Category: "node_patching", Weight: 84, Reason: "node OS patching",
+			Match: matched(func(q string) bool { _, ok := ParsePatchQuery(q); return ok }, "patch request")},
+		{Category: "cluster_scaling", Weight: 80, Reason: "node scaling",
+			Match: matched(func(q string) bool {
+				return strings.Contains(q, "node") && containsAny(q, []string{"add", "remove", "scale"})
+			}, "node scaling intent")},
+		{Category: "rbac", Weight: 75, Reason: "permissions question",
+			Match: func(q string) []string {
+				found := keywords("rbac", "rolebinding", "role binding", "clusterrole", "cluster role",
+					"who can", "who is allowed", "can-i", "what can i do", "my permissions", "over-broad", "cluster-admin")(q)
+				if strings.Contains(q, "what can") && containsAny(q, []string{"serviceaccount", "service account", " sa ", "user ", "group "}) {
+					found = append(found, "what can")
+				}
+				return found
+			}},
+		{Category: "backup", Weight: 73, Reason: "Velero backup or restore",
+			Match: matched(backup.MatchesQuery, "backup request")},
+		{Category: "networking", Weight: 72, Reason: "reachability check",
+			Match: matched(func(q string) bool { _, ok := networking.ParseReachabilityQuery(q); return ok }, "reachability question")},
+		{Category: "networking", Weight: 72, Reason: "expose at a URL",
+			Match: matched(func(q string) bool { _, ok := networking.ParseExposeQuery(q); return ok }, "expose at a URL")},
+		{Category: "storage", Weight: 68, Reason: "PVC expansion or pending claim",
+			Match: matched(func(q string) bool {
+				_, _, expand := storage.ParseExpandQuery(q)
+				_, pending := storage.ParsePendingQuery(q)
+				return expand || pending
+			}, "pvc expansion or pending claim")},
+		{Category: "storage", Weight: 68, Reason: "volume snapshot or restore",
+			Match: matched(func(q string) bool {
+				_, snapshot := storage.ParseSnapshotQuery(q)
+				_, _, restore := storage.ParseRestoreQuery(q)
+				return snapshot || restore
+			}, "volume snapshot or restore")},
+		{Category: "openshift", Weight: 70, Reason: "OpenShift resource",
+			Match: matched(openshift.MatchesQuery, "openshift resource")},
+		{Category: "telemetry", Weight: 65, Reason: "capacity or bin-packing",
+			Match: keywords("capacity", "bin-pack", "binpack", "bin pack", "overcommit", "headroom", "unschedulable")},
+		{Category: "sre", Weight: 60, Reason: "troubleshooting question",
+			Match: keywords("why is", "why are", "why does", "why do", "why did", "why isn't", "why aren't", "why won't",
+				"what is wrong", "what's wrong", "not getting", "not working", "not ready", "not responding", "not reachable",
+				"not starting", "keeps restarting", "crashloop", "diagnose", "troubleshoot", "root cause", "investigate", "debug ")},
+		{Category: "workloads", Weight: 50, Reason: "workload resource",
+			Match: keywords("deploy", "deployment", "pod", "replica", "statefulset", "daemonset")},
+		{Category: "networking", Weight: 45, Reason: "networking resource",
+			Match: keywords("service", "ingress", "loadbalancer", "network", "endpoint")},
+		{Category: "storage", Weight: 40, Reason: "storage resource",
+			Match: keywords("pv", "pvc", "storage", "volume", "configmap", "secret")},
+		{Category: "helm", Weight: 35, Reason: "helm chart or release",
+			Match: keywords("helm", "chart", "release")},
+		{Category: "telemetry", Weight: 30, Reason: "usage or metrics",
+			Match: keywords("metrics", "usage", "top", "cpu", "memory", "resource usage",
+				"utilization", "stats", "statistics", "consumption", "allocat")},
+		{Category: "sre", Weight: 25, Reason: "health or error wording",
+			Match: keywords("health", "healthy", "diagnostic", "error", "issue", "problem", "crash",
+				"failing", "failed", "fix", "remediate", "analyze")},
+	}
+}
+
+// matched turns a predicate into a rule matcher reporting evidence
+func matched(ok func(string) bool, evidence string) func(string) []string {
+	return func(q string) []string {
+		if ok(q) {
+			return []string{evidence}
+		}
+		return nil
+	}
+}
+
+// keywords returns a matcher reporting which of words occur in a question
+func keywords(words ...string) func(string) []string {
+	return func(q string) []string {
+		var found []string
+		for _, w := range words {
+			if strings.Contains(q, w) {
+				found = append(found, strings.TrimSpace(w))
+			}
+		}
+		return found
+	}
+}
+
+// classifyIntent ranks the categories whose rules match question. "general"
+// is always ranked with a score of 1, so a single weak match never reads
+// as certain. Confidence is each candidate's share of the total score.
+func classifyIntent(question string) []IntentCandidate {
+	q := strings.ToLower(question)
+	byCategory := map[string]*IntentCandidate{}
+	var order []string
+	for _, rule := range intentRules() {
+		signals := rule.Match(q)
+		if len(signals) == 0 {
+			continue
+		}
+		score := rule.Weight + math.Min(float64(len(signals)-1), maxIntentBonus)
+		c, ok := byCategory[rule.Category]
+		if !ok {
+			c = &IntentCandidate{Category: rule.Category}
+			byCategory[rule.Category] = c
+			order = append(order, rule.Category)
+		}
+		if score > c.score {
+			c.score, c.Reason = score, rule.Reason
+		}
+		c.Signals = append(c.Signals, signals...)
+	}
+	if _, ok := byCategory["general"]; !ok {
+		byCategory["general"] = &IntentCandidate{Category: "general", Reason: "no category matched", score: 1}
+		order = append(order, "general")
+	}
+
+	candidates := make([]IntentCandidate, 0, len(order))
+	var total float64
+	for _, category := range order {
+		candidates = append(candidates, *byCategory[category])
+		total += byCategory[category].score
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	for i := range candidates {
+		candidates[i].Confidence = math.Round(candidates[i].score/total*100) / 100
+	}
+	return candidates
+}
+
+// intentThreshold returns kubernetes.intent.llm_threshold, the rule
+// confidence below which the model decides. 0 never asks the model and 1
+// always does.
+func intentThreshold() float64 {
+	if viper.IsSet("kubernetes.intent.llm_threshold") {
+		return viper.GetFloat64("kubernetes.intent.llm_threshold")
+	}
+	return defaultIntentThreshold
+}
+
+// refineIntent asks the model for the category when the rules were not
+// confident and the agent has a model. The rule category stands when the
+// model fails or answers with an unknown category.
+func (a *Agent) refineIntent(ctx context.Context, query string, analysis *QueryAnalysis) {
+	ask := a.queryModel()
+	if ask == nil || analysis.Confidence >= intentThreshold() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, entityTimeout)
+	defer cancel()
+	response, err := ask(ctx, intentPrompt(query, analysis.Candidates))
+	if err != nil {
+		logger.Debugf("intent classification failed, keeping %s: %v", analysis.Category, err)
+		return
+	}
+	category, confidence, err := parseIntent(response)
+	if err != nil {
+		logger.Debugf("intent classification parse failed, keeping %s: %v", analysis.Category, err)
+		return
+	}
+	logger.Debugf("model classified %q as %s (%.2f), rules said %s (%.2f)", query, category, confidence, analysis.Category, analysis.Confidence)
+	analysis.Category, analysis.Confidence, analysis.ClassifiedBy = category, confidence, "model"
+	analysis.applyCategory()
+}
+
+func intentPrompt(query string, candidates []IntentCandidate) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Classify this Kubernetes request into one category.\n\nRequest: %s\n\nCategories:\n", query)
+	for _, name := range intentCategoryNames() {
+		fmt.Fprintf(&b, "- %s: %s\n", name, intentCategories[name])
+	}
+	if len(candidates) > 0 {
+		b.WriteString("\nKeyword rules suggested, most likely first:")
+		for _, c := range candidates {
+			fmt.Fprintf(&b, " %s (%.2f)", c.Category, c.Confidence)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(`
+Return a JSON object: {"category": "<one of the categories>", "confidence": <0 to 1>}
+Return valid JSON only.`)
+	return b.String()
+}
+
+// intentCategoryNames lists the categories in rule precedence order
+func intentCategoryNames() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, r := range intentRules() {
+		if !seen[r.Category] {
+			seen[r.Category] = true
+			names = append(names, r.Category)
+		}
+	}
+	return append(names, "general")
+}
+
+func parseIntent(response string) (string, float64, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return "", 0, fmt.Errorf("no valid JSON found in response")
+	}
+	var parsed struct {
+		Category   string  `json:"category"`
+		Confidence float64 `json:"confidence"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return "", 0, fmt.Errorf("invalid intent JSON: %w", err)
+	}
+	category := strings.ToLower(strings.TrimSpace(parsed.Category))
+	if _, ok := intentCategories[category]; !ok {
+		return "", 0, fmt.Errorf("unknown category %q", parsed.Category)
+	}
+	confidence := parsed.Confidence
+	if confidence <= 0 || confidence > 1 {
+		confidence = 1
+	}
+	return category, confidence, nil
+}
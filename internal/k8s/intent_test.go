@@ -0,0 +1,140 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// intentEvalSuite is a recorded set of questions users asked the k8s agent,
+// with the category that answers them. Each one that the keyword checks
+// misrouted is marked; add new misroutes here before fixing the rules.
+var intentEvalSuite = []struct {
+	query    string
+	category string
+	misroute bool // routed elsewhere by the keyword checks this replaced
+}{
+	{"why are my services not getting endpoints", "sre", true},
+	{"why is deployment api failing in prod", "sre", true},
+	{"pods keep restarting in the payments namespace, what's wrong", "sre", true},
+	{"ingress not reachable from outside the cluster", "sre", true},
+	{"troubleshoot the pending pvc for postgres", "storage", true},
+	{"why is my pvc pending", "storage", true},
+	{"list pods in kube-system", "workloads", false},
+	{"scale deployment web to 5 replicas", "workloads", false},
+	{"restart the checkout deployment", "workloads", false},
+	{"show services in namespace shop", "networking", false},
+	{"list ingresses", "networking", false},
+	{"can pod web reach service api on port 8080", "networking", true},
+	{"expose web on port 8080 at https://shop.example.com/api", "networking", true},
+	{"what pvcs are unbound", "storage", false},
+	{"show configmap app-config", "storage", false},
+	{"expand pvc data to 50Gi", "storage", false},
+	{"snapshot the postgres pvc before upgrade", "storage", false},
+	{"restore snapshot data-20261015-1200 into pvc data-restored", "storage", false},
+	{"install velero with bucket cluster-backups", "backup", false},
+	{"back up the shop namespace and keep it for 7 days", "backup", false},
+	{"list velero backups with their sizes", "backup", false},
+	{"restore namespace shop from the latest backup into shop-restored", "backup", false},
+	{"why did the nightly velero backup fail", "sre", false},
+	{"list helm releases", "helm", false},
+	{"top pods by memory", "workloads", false},
+	{"cpu utilization across nodes", "telemetry", false},
+	{"how much capacity is left for pods", "telemetry", false},
+	{"who can delete pods in prod", "rbac", false},
+	{"list openshift routes", "openshift", false},
+	{"upgrade eks cluster prod to 1.30", "cluster_upgrade", false},
+	{"upgrade eks cluster prod to 1.30 and snapshot the postgres pvc first", "cluster_upgrade", false},
+	{"patch and reboot all nodes of cluster prod one at a time", "node_patching", false},
+	{"apply security updates to the nodes of the lab kubeadm cluster", "node_patching", false},
+	{"roll the node groups of eks cluster prod onto the latest ami", "node_patching", false},
+	{"why did node ip-10-0-1-5 reboot last night", "sre", false},
+	{"create an eks cluster called staging", "cluster_provisioning", false},
+	{"add 2 nodes to the cluster", "cluster_scaling", false},
+	{"is the cluster healthy", "sre", false},
+	{"hello", "general", false},
+}
+
+func TestIntentEvalSuite(t *testing.T) {
+	correct, misroutes := 0, 0
+	for _, tt := range intentEvalSuite {
+		if tt.misroute {
+			misroutes++
+		}
+		got := classifyIntent(tt.query)[0].Category
+		if got == tt.category {
+			correct++
+			continue
+		}
+		t.Errorf("classifyIntent(%q) = %s, want %s", tt.query, got, tt.category)
+	}
+	t.Logf("%d/%d questions classified correctly, %d of them former misroutes", correct, len(intentEvalSuite), misroutes)
+}
+
+func TestClassifyIntentConfidence(t *testing.T) {
+	candidates := classifyIntent("list pods")
+	if candidates[0].Category != "workloads" || candidates[0].Confidence < defaultIntentThreshold {
+		t.Errorf("list pods = %+v, want confident workloads", candidates[0])
+	}
+	if last := candidates[len(candidates)-1]; last.Category != "general" {
+		t.Errorf("last candidate = %+v, want general", last)
+	}
+
+	// Troubleshooting wording and a resource compete, so the model decides
+	candidates = classifyIntent("why are my services not getting endpoints")
+	if candidates[0].Category != "sre" || candidates[0].Confidence >= defaultIntentThreshold {
+		t.Errorf("services question = %+v, want sre below the threshold", candidates[0])
+	}
+	if candidates[1].Category != "networking" {
+		t.Errorf("runner-up = %+v, want networking", candidates[1])
+	}
+}
+
+func TestRefineIntent(t *testing.T) {
+	a := &Agent{}
+	var asked int
+	a.SetQueryModel(func(context.Context, string) (string, error) {
+		asked++
+		return `{"category": "networking", "confidence": 0.8}`, nil
+	})
+
+	// Confident rules skip the model
+	analysis := a.analyzeQuery("list pods")
+	a.refineIntent(context.Background(), "list pods", &analysis)
+	if asked != 0 || analysis.Category != "workloads" || analysis.ClassifiedBy != "rules" {
+		t.Errorf("confident analysis = %+v after %d model calls", analysis, asked)
+	}
+
+	query := "why are my services not getting endpoints"
+	analysis = a.analyzeQuery(query)
+	a.refineIntent(context.Background(), query, &analysis)
+	if asked != 1 || analysis.Category != "networking" || analysis.ClassifiedBy != "model" || analysis.Confidence != 0.8 {
+		t.Errorf("refined analysis = %+v after %d model calls", analysis, asked)
+	}
+
+	// A failing or nonsensical model keeps the rule category
+	for _, fn := range []AIDecisionFunc{
+		func(context.Context, string) (string, error) { return "", errors.New("no model") },
+		func(context.Context, string) (string, error) { return `{"category": "databases"}`, nil },
+	} {
+		a.SetQueryModel(fn)
+		analysis = a.analyzeQuery(query)
+		a.refineIntent(context.Background(), query, &analysis)
+		if analysis.Category != "sre" || analysis.ClassifiedBy != "rules" {
+			t.Errorf("analysis after a bad model answer = %+v", analysis)
+		}
+	}
+
+	// A threshold of 0 never asks the model
+	viper.Set("kubernetes.intent.llm_threshold", 0.0)
+	t.Cleanup(func() { viper.Set("kubernetes.intent.llm_threshold", nil) })
+	asked = 0
+	a.SetQueryModel(func(context.Context, string) (string, error) { asked++; return "", nil })
+	analysis = a.analyzeQuery(query)
+	a.refineIntent(context.Background(), query, &analysis)
+	if asked != 0 {
+		t.Errorf("model asked %d times with the threshold at 0", asked)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"text/tabwriter"
 	"time"
 
+	"github.com/bgdnvk/clanker/internal/k8s/workloads"
 	"github.com/spf13/viper"
 )
 
@@ -113,6 +115,89 @@ func (c *Client) ExecuteOperations(ctx context.Context, operations []K8sOperatio
 	return k8sResults.String(), nil
 }
 
+// listPods runs list_pods with the client's selectors added to the
+// operation's. With a summary threshold it pages through the pods, and
+// counts them per namespace and phase instead once there are more.
+func (c *Client) listPods(ctx context.Context, namespace string, allNamespaces bool, labelSelector string) (string, error) {
+	opts := workloads.QueryOptions{
+		AllNamespaces:    allNamespaces,
+		LabelSelector:    joinSelectors(c.listOpts.LabelSelector, labelSelector),
+		FieldSelector:    c.listOpts.FieldSelector,
+		SummaryThreshold: c.listOpts.SummaryThreshold,
+	}
+
+	if limit := opts.SummaryLimit(); limit > 0 {
+		listNamespace := ""
+		if !allNamespaces {
+			listNamespace = c.nativeNamespace(namespace)
+		}
+		pods, summary, err := c.podsOrSummary(ctx, listNamespace, opts.LabelSelector, opts.FieldSelector, limit)
+		if err != nil {
+			return "", err
+		}
+		if summary != nil {
+			return summary.String(), nil
+		}
+		return formatPodTable(pods, listNamespace), nil
+	}
+
+	args := []string{"get", "pods", "-o", "wide"}
+	if opts.LabelSelector != "" {
+		args = append(args, "-l", opts.LabelSelector)
+	}
+	if opts.FieldSelector != "" {
+		args = append(args, "--field-selector", opts.FieldSelector)
+	}
+	if allNamespaces {
+		return c.Run(ctx, append(args, "-A")...)
+	}
+	return c.RunWithNamespace(ctx, namespace, args...)
+}
+
+// formatPodTable prints pods in the columns of kubectl get pods -o wide
+// that the pod list carries, with a NAMESPACE column when namespace is
+// empty
+func formatPodTable(pods []ClusterPodInfo, namespace string) string {
+	if len(pods) == 0 {
+		if namespace == "" {
+			return "No resources found\n"
+		}
+		return fmt.Sprintf("No resources found in %s namespace.\n", namespace)
+	}
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 3, ' ', 0)
+	if namespace == "" {
+		_, _ = fmt.Fprint(w, "NAMESPACE\t")
+	}
+	_, _ = fmt.Fprintln(w, "NAME\tREADY\tSTATUS\tRESTARTS\tIP\tNODE")
+	for _, pod := range pods {
+		if namespace == "" {
+			_, _ = fmt.Fprintf(w, "%s\t", pod.Namespace)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", pod.Name, pod.Ready, podStatus(pod), pod.Restarts, orNone(pod.IP), orNone(pod.Node))
+	}
+	_ = w.Flush()
+	return b.String()
+}
+
+// podStatus is the reason a container is not running, such as
+// CrashLoopBackOff, or else the pod's phase
+func podStatus(pod ClusterPodInfo) string {
+	for _, c := range pod.Containers {
+		if !c.Ready && c.State != "" && c.State != "running" && c.State != "unknown" {
+			return c.State
+		}
+	}
+	return pod.Phase
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
+
 // executeK8sOperation executes a single K8s operation based on its type
 func (c *Client) executeK8sOperation(ctx context.Context, op K8sOperation) (string, error) {
 	// Extract common parameters
@@ -157,18 +242,10 @@ func (c *Client) executeK8sOperation(ctx context.Context, op K8sOperation) (stri
 
 	// WORKLOADS
 	case "list_pods":
-		args := []string{"get", "pods", "-o", "wide"}
-		if labelSelector != "" {
-			args = append(args, "-l", labelSelector)
-		}
-		if allNamespaces {
-			args = append(args, "-A")
-			return c.Run(ctx, args...)
-		}
-		if namespace == "" {
+		if namespace == "" && !allNamespaces {
 			namespace = "default"
 		}
-		return c.RunWithNamespace(ctx, namespace, args...)
+		return c.listPods(ctx, namespace, allNamespaces, labelSelector)
 
 	case "get_pod_details":
 		if name == "" {
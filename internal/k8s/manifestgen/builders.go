@@ -0,0 +1,363 @@
+package manifestgen
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// The builders expect a spec that passed Validate; Build checks it first.
+// Pods are selected by app=<name>, the label `kubectl create deployment`
+// uses, so plans and follow-up commands can keep using it.
+
+func selectorLabels(spec AppSpec) map[string]string {
+	return map[string]string{"app": spec.Name}
+}
+
+func objectMeta(spec AppSpec) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      spec.Name,
+		Namespace: spec.Namespace,
+		Labels: map[string]string{
+			"app":                    spec.Name,
+			"app.kubernetes.io/name": spec.Name,
+			ManagedByLabel:           "clanker",
+		},
+	}
+}
+
+// container builds the application's container
+func container(spec AppSpec) corev1.Container {
+	c := corev1.Container{
+		Name:      spec.Name,
+		Image:     spec.Image,
+		Command:   spec.Command,
+		Resources: resourceRequirements(spec.Resources),
+	}
+	if spec.Port != 0 && !spec.IsBatch() {
+		c.Ports = []corev1.ContainerPort{{Name: "http", ContainerPort: spec.Port, Protocol: corev1.ProtocolTCP}}
+	}
+	for _, env := range spec.Env {
+		c.Env = append(c.Env, corev1.EnvVar{Name: env.Name, Value: env.Value})
+	}
+	if spec.Probe != nil {
+		readiness, liveness := spec.Probe.Readiness, spec.Probe.Liveness
+		if !readiness && !liveness {
+			readiness, liveness = true, true
+		}
+		if readiness {
+			c.ReadinessProbe = probe(spec, 5, 10)
+		}
+		if liveness {
+			c.LivenessProbe = probe(spec, 15, 20)
+		}
+	}
+	if spec.Volume != nil {
+		c.VolumeMounts = []corev1.VolumeMount{{Name: volumeName, MountPath: spec.Volume.MountPath}}
+	}
+	return c
+}
+
+// podTemplate builds the pod template every workload kind runs
+func podTemplate(spec AppSpec) corev1.PodTemplateSpec {
+	template := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: selectorLabels(spec)},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{container(spec)}},
+	}
+	if spec.IsBatch() {
+		template.Spec.RestartPolicy = corev1.RestartPolicyOnFailure
+	}
+	if spec.PullSecret != "" {
+		template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: spec.PullSecret}}
+	}
+	return template
+}
+
+// Deployment builds the application's Deployment
+func Deployment(spec AppSpec) *appsv1.Deployment {
+	spec = spec.withDefaults()
+	deployment := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: objectMeta(spec),
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: selectorLabels(spec)},
+			Template: podTemplate(spec),
+		},
+	}
+	// Leave replicas unset under an HPA so re-applying does not reset it
+	if spec.Autoscale == nil {
+		replicas := spec.Replicas
+		deployment.Spec.Replicas = &replicas
+	}
+	return deployment
+}
+
+// volumeName names a StatefulSet's claim template and the mount using it
+const volumeName = "data"
+
+// StatefulSet builds the application's StatefulSet, governed by the
+// Service of the same name and claiming its volume per pod
+func StatefulSet(spec AppSpec) *appsv1.StatefulSet {
+	spec = spec.withDefaults()
+	statefulSet := &appsv1.StatefulSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "StatefulSet"},
+		ObjectMeta: objectMeta(spec),
+		Spec: appsv1.StatefulSetSpec{
+			ServiceName: spec.Name,
+			Selector:    &metav1.LabelSelector{MatchLabels: selectorLabels(spec)},
+			Template:    podTemplate(spec),
+		},
+	}
+	if spec.Autoscale == nil {
+		replicas := spec.Replicas
+		statefulSet.Spec.Replicas = &replicas
+	}
+	if v := spec.Volume; v != nil {
+		claim := corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: volumeName},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(v.Size)},
+				},
+			},
+		}
+		if v.StorageClass != "" {
+			storageClass := v.StorageClass
+			claim.Spec.StorageClassName = &storageClass
+		}
+		statefulSet.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{claim}
+	}
+	return statefulSet
+}
+
+// Job builds a Job that runs the application once to completion
+func Job(spec AppSpec) *batchv1.Job {
+	spec = spec.withDefaults()
+	return &batchv1.Job{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "Job"},
+		ObjectMeta: objectMeta(spec),
+		Spec:       batchv1.JobSpec{Template: podTemplate(spec)},
+	}
+}
+
+// CronJob builds a CronJob that runs the application on its schedule. Runs
+// never overlap, so a slow nightly job is not started twice.
+func CronJob(spec AppSpec) *batchv1.CronJob {
+	spec = spec.withDefaults()
+	return &batchv1.CronJob{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"},
+		ObjectMeta: objectMeta(spec),
+		Spec: batchv1.CronJobSpec{
+			Schedule:          spec.Schedule,
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{Template: podTemplate(spec)},
+			},
+		},
+	}
+}
+
+// workload builds the object of the spec's kind
+func workload(spec AppSpec) any {
+	switch spec.WorkloadKind() {
+	case KindStatefulSet:
+		return StatefulSet(spec)
+	case KindJob:
+		return Job(spec)
+	case KindCronJob:
+		return CronJob(spec)
+	}
+	return Deployment(spec)
+}
+
+func resourceRequirements(res Resources) corev1.ResourceRequirements {
+	var reqs corev1.ResourceRequirements
+	set := func(list *corev1.ResourceList, name corev1.ResourceName, value string) {
+		if value == "" {
+			return
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return
+		}
+		if *list == nil {
+			*list = corev1.ResourceList{}
+		}
+		(*list)[name] = q
+	}
+	set(&reqs.Requests, corev1.ResourceCPU, res.CPURequest)
+	set(&reqs.Requests, corev1.ResourceMemory, res.MemoryRequest)
+	set(&reqs.Limits, corev1.ResourceCPU, res.CPULimit)
+	set(&reqs.Limits, corev1.ResourceMemory, res.MemoryLimit)
+	return reqs
+}
+
+func probe(spec AppSpec, initialDelay, period int32) *corev1.Probe {
+	handler := corev1.ProbeHandler{}
+	if spec.Probe.Path != "" {
+		handler.HTTPGet = &corev1.HTTPGetAction{Path: spec.Probe.Path, Port: intstr.FromInt32(spec.Port)}
+	} else {
+		handler.TCPSocket = &corev1.TCPSocketAction{Port: intstr.FromInt32(spec.Port)}
+	}
+	return &corev1.Probe{
+		ProbeHandler:        handler,
+		InitialDelaySeconds: initialDelay,
+		PeriodSeconds:       period,
+	}
+}
+
+// Service builds the Service in front of the application's port. A
+// StatefulSet's ClusterIP Service is headless, giving each pod a stable
+// DNS name.
+func Service(spec AppSpec) *corev1.Service {
+	spec = spec.withDefaults()
+	service := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: objectMeta(spec),
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceType(spec.ServiceType),
+			Selector: selectorLabels(spec),
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       spec.Port,
+				TargetPort: intstr.FromInt32(spec.Port),
+				Protocol:   corev1.ProtocolTCP,
+			}},
+		},
+	}
+	if spec.Kind == KindStatefulSet && spec.ServiceType == string(corev1.ServiceTypeClusterIP) {
+		service.Spec.ClusterIP = corev1.ClusterIPNone
+	}
+	return service
+}
+
+// Ingress builds an Ingress routing the spec's host to its Service
+func Ingress(spec AppSpec) *networkingv1.Ingress {
+	spec = spec.withDefaults()
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+		ObjectMeta: objectMeta(spec),
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				Host: spec.Ingress.Host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     spec.Ingress.Path,
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: spec.Name,
+									Port: networkingv1.ServiceBackendPort{Number: spec.Port},
+								},
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+	if spec.Ingress.ClassName != "" {
+		className := spec.Ingress.ClassName
+		ingress.Spec.IngressClassName = &className
+	}
+	if spec.Ingress.TLSSecret != "" {
+		ingress.Spec.TLS = []networkingv1.IngressTLS{{
+			Hosts:      []string{spec.Ingress.Host},
+			SecretName: spec.Ingress.TLSSecret,
+		}}
+	}
+	return ingress
+}
+
+// HorizontalPodAutoscaler builds a CPU utilization autoscaler for the
+// Deployment or StatefulSet
+func HorizontalPodAutoscaler(spec AppSpec) *autoscalingv2.HorizontalPodAutoscaler {
+	spec = spec.withDefaults()
+	minReplicas := spec.Autoscale.MinReplicas
+	cpuPercent := spec.Autoscale.CPUPercent
+	return &autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "autoscaling/v2", Kind: "HorizontalPodAutoscaler"},
+		ObjectMeta: objectMeta(spec),
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       spec.Kind,
+				Name:       spec.Name,
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: spec.Autoscale.MaxReplicas,
+			Metrics: []autoscalingv2.MetricSpec{{
+				Type: autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricSource{
+					Name: corev1.ResourceCPU,
+					Target: autoscalingv2.MetricTarget{
+						Type:               autoscalingv2.UtilizationMetricType,
+						AverageUtilization: &cpuPercent,
+					},
+				},
+			}},
+		},
+	}
+}
+
+// PodDisruptionBudget builds a budget keeping MinAvailable pods up during
+// voluntary disruptions such as node drains
+func PodDisruptionBudget(spec AppSpec) *policyv1.PodDisruptionBudget {
+	spec = spec.withDefaults()
+	minAvailable := intstr.FromInt32(spec.PDB.MinAvailable)
+	return &policyv1.PodDisruptionBudget{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "policy/v1", Kind: "PodDisruptionBudget"},
+		ObjectMeta: objectMeta(spec),
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector:     &metav1.LabelSelector{MatchLabels: selectorLabels(spec)},
+		},
+	}
+}
+
+// NetworkPolicy builds a policy admitting traffic to the application's pods
+// only from its own namespace and the allowed namespaces (and from anywhere
+// when the Service is exposed outside the cluster), on its port when it has
+// one
+func NetworkPolicy(spec AppSpec) *networkingv1.NetworkPolicy {
+	spec = spec.withDefaults()
+
+	peers := []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}}
+	for _, ns := range spec.NetworkPolicy.AllowNamespaces {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"kubernetes.io/metadata.name": ns},
+			},
+		})
+	}
+	// A LoadBalancer or NodePort Service forwards client traffic from
+	// outside the cluster, which no namespace selector matches
+	if spec.ServiceType != string(corev1.ServiceTypeClusterIP) {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: "0.0.0.0/0"}})
+	}
+	rule := networkingv1.NetworkPolicyIngressRule{From: peers}
+	if spec.Port != 0 {
+		protocol := corev1.ProtocolTCP
+		port := intstr.FromInt32(spec.Port)
+		rule.Ports = []networkingv1.NetworkPolicyPort{{Protocol: &protocol, Port: &port}}
+	}
+
+	return &networkingv1.NetworkPolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+		ObjectMeta: objectMeta(spec),
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: selectorLabels(spec)},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{rule},
+		},
+	}
+}
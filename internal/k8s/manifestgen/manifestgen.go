@@ -0,0 +1,482 @@
+// Package manifestgen builds Kubernetes manifests for an application from a
+// typed spec instead of free-form YAML. Objects are built from the upstream
+// API types, so the YAML is schema-valid by construction; Validate catches
+// the values the types cannot (names, quantities, ports).
+package manifestgen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// ManagedByLabel marks objects generated by clanker
+const ManagedByLabel = "app.kubernetes.io/managed-by"
+
+// Workload kinds an AppSpec can generate
+const (
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindJob         = "Job"
+	KindCronJob     = "CronJob"
+)
+
+// AppSpec describes an application to deploy. The workload of its Kind is
+// always generated; the other objects are generated when their section is
+// set.
+type AppSpec struct {
+	Kind          string             `json:"kind,omitempty"` // Deployment (default), StatefulSet, Job or CronJob
+	Name          string             `json:"name"`
+	Namespace     string             `json:"namespace,omitempty"`
+	Image         string             `json:"image"`
+	PullSecret    string             `json:"pull_secret,omitempty"` // imagePullSecret for a private registry
+	Command       []string           `json:"command,omitempty"`     // replaces the image's entrypoint
+	Schedule      string             `json:"schedule,omitempty"`    // cron schedule of a CronJob
+	Replicas      int32              `json:"replicas,omitempty"`
+	Port          int32              `json:"port,omitempty"`   // container port; a Service is generated when set, except for Jobs
+	Volume        *VolumeSpec        `json:"volume,omitempty"` // a StatefulSet's volume, one claim per pod
+	Env           []EnvVar           `json:"env,omitempty"`
+	Resources     Resources          `json:"resources,omitempty"`
+	Probe         *ProbeSpec         `json:"probe,omitempty"`
+	ServiceType   string             `json:"service_type,omitempty"` // ClusterIP (default), NodePort, LoadBalancer
+	Ingress       *IngressSpec       `json:"ingress,omitempty"`
+	Autoscale     *AutoscaleSpec     `json:"autoscale,omitempty"`
+	PDB           *PDBSpec           `json:"pdb,omitempty"`
+	NetworkPolicy *NetworkPolicySpec `json:"network_policy,omitempty"`
+}
+
+// EnvVar is a plain container environment variable
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Resources holds container requests and limits as quantity strings
+type Resources struct {
+	CPURequest    string `json:"cpu_request,omitempty"`
+	CPULimit      string `json:"cpu_limit,omitempty"`
+	MemoryRequest string `json:"memory_request,omitempty"`
+	MemoryLimit   string `json:"memory_limit,omitempty"`
+}
+
+// ProbeSpec configures readiness and liveness probes. An HTTP GET probe is
+// used when Path is set, a TCP probe otherwise. When neither Readiness nor
+// Liveness is set, both probes are generated.
+type ProbeSpec struct {
+	Path      string `json:"path,omitempty"`
+	Readiness bool   `json:"readiness,omitempty"`
+	Liveness  bool   `json:"liveness,omitempty"`
+}
+
+// VolumeSpec is the persistent volume each StatefulSet pod claims
+type VolumeSpec struct {
+	Size         string `json:"size"`
+	MountPath    string `json:"mount_path,omitempty"` // defaults to the image's data directory, or /data
+	StorageClass string `json:"storage_class,omitempty"`
+}
+
+// IngressSpec routes a host to the application's Service
+type IngressSpec struct {
+	Host      string `json:"host"`
+	Path      string `json:"path,omitempty"` // defaults to /
+	ClassName string `json:"class_name,omitempty"`
+	TLSSecret string `json:"tls_secret,omitempty"`
+}
+
+// AutoscaleSpec configures a CPU based HorizontalPodAutoscaler
+type AutoscaleSpec struct {
+	MinReplicas int32 `json:"min_replicas"`
+	MaxReplicas int32 `json:"max_replicas"`
+	CPUPercent  int32 `json:"cpu_percent,omitempty"` // defaults to 80
+}
+
+// PDBSpec configures a PodDisruptionBudget
+type PDBSpec struct {
+	MinAvailable int32 `json:"min_available"`
+}
+
+// NetworkPolicySpec restricts ingress traffic to the application's pods to
+// pods in its own namespace and in AllowNamespaces
+type NetworkPolicySpec struct {
+	AllowNamespaces []string `json:"allow_namespaces,omitempty"`
+}
+
+// Manifest is one rendered object
+type Manifest struct {
+	Kind       string
+	APIVersion string
+	Name       string
+	Namespace  string
+	Content    string
+}
+
+// dataDirs are where well known images keep their data, used to mount a
+// StatefulSet volume when the spec does not say where
+var dataDirs = map[string]string{
+	"postgres":      "/var/lib/postgresql/data",
+	"mysql":         "/var/lib/mysql",
+	"mariadb":       "/var/lib/mysql",
+	"mongo":         "/data/db",
+	"redis":         "/data",
+	"rabbitmq":      "/var/lib/rabbitmq",
+	"elasticsearch": "/usr/share/elasticsearch/data",
+	"prometheus":    "/prometheus",
+	"grafana":       "/var/lib/grafana",
+}
+
+// WorkloadKind returns the kind of workload the spec generates
+func (s AppSpec) WorkloadKind() string {
+	if s.Kind == "" {
+		return KindDeployment
+	}
+	return s.Kind
+}
+
+// IsBatch reports whether the spec runs to completion as a Job or CronJob,
+// rather than serving
+func (s AppSpec) IsBatch() bool {
+	return s.Kind == KindJob || s.Kind == KindCronJob
+}
+
+// withDefaults fills in the values every builder relies on
+func (s AppSpec) withDefaults() AppSpec {
+	s.Kind = s.WorkloadKind()
+	if s.Namespace == "" {
+		s.Namespace = "default"
+	}
+	if s.Replicas == 0 && s.Autoscale == nil {
+		s.Replicas = 1
+	}
+	if s.ServiceType == "" {
+		s.ServiceType = "ClusterIP"
+	}
+	// Copy the sections so defaults never leak into the caller's spec
+	if s.Ingress != nil {
+		ingress := *s.Ingress
+		if ingress.Path == "" {
+			ingress.Path = "/"
+		}
+		s.Ingress = &ingress
+	}
+	if s.Volume != nil {
+		volume := *s.Volume
+		if volume.MountPath == "" {
+			volume.MountPath = dataDirs[imageBaseName(s.Image)]
+		}
+		if volume.MountPath == "" {
+			volume.MountPath = "/data"
+		}
+		s.Volume = &volume
+	}
+	if s.Autoscale != nil {
+		autoscale := *s.Autoscale
+		if autoscale.CPUPercent == 0 {
+			autoscale.CPUPercent = 80
+		}
+		s.Autoscale = &autoscale
+		// Utilization targets are relative to the request, so the HPA
+		// never scales a container without one
+		if s.Resources.CPURequest == "" {
+			s.Resources.CPURequest = "100m"
+		}
+	}
+	return s
+}
+
+// Validate checks the values the API types do not constrain
+func (s AppSpec) Validate() error {
+	s = s.withDefaults()
+	var errs []error
+	addErrs := func(field string, msgs []string) {
+		for _, msg := range msgs {
+			errs = append(errs, fmt.Errorf("%s: %s", field, msg))
+		}
+	}
+
+	// Service names must be DNS-1035 labels, which also satisfies the
+	// other kinds
+	addErrs("name", validation.IsDNS1035Label(s.Name))
+	addErrs("namespace", validation.IsDNS1123Label(s.Namespace))
+
+	if s.Image == "" {
+		errs = append(errs, fmt.Errorf("image is required"))
+	} else if strings.ContainsAny(s.Image, " \t\n") {
+		errs = append(errs, fmt.Errorf("image: %q must not contain whitespace", s.Image))
+	}
+	if s.Replicas < 0 {
+		errs = append(errs, fmt.Errorf("replicas: must not be negative"))
+	}
+	errs = append(errs, validateKind(s)...)
+	if s.Port != 0 {
+		addErrs("port", validation.IsValidPortNum(int(s.Port)))
+	}
+
+	seen := make(map[string]bool)
+	for _, env := range s.Env {
+		addErrs("env "+env.Name, validation.IsEnvVarName(env.Name))
+		if seen[env.Name] {
+			errs = append(errs, fmt.Errorf("env %s: set more than once", env.Name))
+		}
+		seen[env.Name] = true
+	}
+
+	errs = append(errs, validateResourcePair("cpu", s.Resources.CPURequest, s.Resources.CPULimit)...)
+	errs = append(errs, validateResourcePair("memory", s.Resources.MemoryRequest, s.Resources.MemoryLimit)...)
+
+	switch s.ServiceType {
+	case "ClusterIP", "NodePort", "LoadBalancer":
+	default:
+		errs = append(errs, fmt.Errorf("service_type: %q must be ClusterIP, NodePort or LoadBalancer", s.ServiceType))
+	}
+
+	if s.Probe != nil && s.Port == 0 {
+		errs = append(errs, fmt.Errorf("probe: a port is required"))
+	}
+	if s.Probe != nil && s.Probe.Path != "" && !strings.HasPrefix(s.Probe.Path, "/") {
+		errs = append(errs, fmt.Errorf("probe: path %q must start with /", s.Probe.Path))
+	}
+
+	if s.Ingress != nil {
+		if s.Port == 0 {
+			errs = append(errs, fmt.Errorf("ingress: a port is required"))
+		}
+		addErrs("ingress host", validation.IsDNS1123Subdomain(s.Ingress.Host))
+		if !strings.HasPrefix(s.Ingress.Path, "/") {
+			errs = append(errs, fmt.Errorf("ingress: path %q must start with /", s.Ingress.Path))
+		}
+		if s.Ingress.TLSSecret != "" {
+			addErrs("ingress tls_secret", validation.IsDNS1123Subdomain(s.Ingress.TLSSecret))
+		}
+	}
+
+	if as := s.Autoscale; as != nil {
+		if as.MinReplicas < 1 {
+			errs = append(errs, fmt.Errorf("autoscale: min_replicas must be at least 1"))
+		}
+		if as.MaxReplicas < as.MinReplicas {
+			errs = append(errs, fmt.Errorf("autoscale: max_replicas %d is below min_replicas %d", as.MaxReplicas, as.MinReplicas))
+		}
+		if as.CPUPercent < 1 {
+			errs = append(errs, fmt.Errorf("autoscale: cpu_percent must be positive"))
+		}
+	}
+
+	if s.PDB != nil {
+		floor := s.Replicas
+		if s.Autoscale != nil {
+			floor = s.Autoscale.MinReplicas
+		}
+		if s.PDB.MinAvailable < 0 {
+			errs = append(errs, fmt.Errorf("pdb: min_available must not be negative"))
+		} else if s.PDB.MinAvailable >= floor {
+			errs = append(errs, fmt.Errorf("pdb: min_available %d must be below the %d replicas, or node drains block", s.PDB.MinAvailable, floor))
+		}
+	}
+
+	if s.NetworkPolicy != nil {
+		for _, ns := range s.NetworkPolicy.AllowNamespaces {
+			addErrs("network_policy namespace", validation.IsDNS1123Label(ns))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateKind checks the fields that only some workload kinds use
+func validateKind(s AppSpec) []error {
+	var errs []error
+	switch s.Kind {
+	case KindDeployment, KindStatefulSet, KindJob, KindCronJob:
+	default:
+		return []error{fmt.Errorf("kind: %q must be Deployment, StatefulSet, Job or CronJob", s.Kind)}
+	}
+
+	if s.Kind == KindCronJob && s.Schedule == "" {
+		errs = append(errs, fmt.Errorf("schedule: a CronJob needs one"))
+	} else if s.Kind == KindCronJob && !validSchedule(s.Schedule) {
+		errs = append(errs, fmt.Errorf("schedule: %q must be five cron fields or a macro such as @daily", s.Schedule))
+	} else if s.Kind != KindCronJob && s.Schedule != "" {
+		errs = append(errs, fmt.Errorf("schedule: only a CronJob runs on a schedule"))
+	}
+
+	if v := s.Volume; v != nil {
+		if s.Kind != KindStatefulSet {
+			errs = append(errs, fmt.Errorf("volume: only a StatefulSet claims volumes"))
+		}
+		if q, err := resource.ParseQuantity(v.Size); err != nil || q.Sign() <= 0 {
+			errs = append(errs, fmt.Errorf("volume: size %q must be a positive quantity such as 20Gi", v.Size))
+		}
+		if !strings.HasPrefix(v.MountPath, "/") {
+			errs = append(errs, fmt.Errorf("volume: mount_path %q must start with /", v.MountPath))
+		}
+		if v.StorageClass != "" {
+			for _, msg := range validation.IsDNS1123Subdomain(v.StorageClass) {
+				errs = append(errs, fmt.Errorf("volume storage_class: %s", msg))
+			}
+		}
+	}
+
+	if s.IsBatch() {
+		// Batch pods run to completion, so nothing routes to or scales them
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{
+			{"service_type", s.ServiceType != "ClusterIP"},
+			{"ingress", s.Ingress != nil},
+			{"autoscale", s.Autoscale != nil},
+			{"pdb", s.PDB != nil},
+			{"probe", s.Probe != nil},
+		} {
+			if f.set {
+				errs = append(errs, fmt.Errorf("%s: not supported for a %s", f.name, s.Kind))
+			}
+		}
+	}
+	return errs
+}
+
+var cronFieldPattern = regexp.MustCompile(`^[0-9A-Za-z*?/,-]+$`)
+
+// validSchedule accepts five cron fields or a predefined macro
+func validSchedule(schedule string) bool {
+	switch schedule {
+	case "@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly":
+		return true
+	}
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 {
+		return false
+	}
+	for _, f := range fields {
+		if !cronFieldPattern.MatchString(f) {
+			return false
+		}
+	}
+	return true
+}
+
+func validateResourcePair(name, request, limit string) []error {
+	var errs []error
+	var req, lim resource.Quantity
+	var err error
+	if request != "" {
+		if req, err = resource.ParseQuantity(request); err != nil {
+			errs = append(errs, fmt.Errorf("%s request %q: %w", name, request, err))
+		}
+	}
+	if limit != "" {
+		if lim, err = resource.ParseQuantity(limit); err != nil {
+			errs = append(errs, fmt.Errorf("%s limit %q: %w", name, limit, err))
+		}
+	}
+	if len(errs) == 0 && request != "" && limit != "" && req.Cmp(lim) > 0 {
+		errs = append(errs, fmt.Errorf("%s request %s is above the limit %s", name, request, limit))
+	}
+	return errs
+}
+
+// Build validates the spec and renders every object it asks for, in apply
+// order
+func Build(spec AppSpec) ([]Manifest, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	spec = spec.withDefaults()
+
+	objects := []any{workload(spec)}
+	if spec.Port != 0 && !spec.IsBatch() {
+		objects = append(objects, Service(spec))
+	}
+	if spec.Ingress != nil {
+		objects = append(objects, Ingress(spec))
+	}
+	if spec.Autoscale != nil {
+		objects = append(objects, HorizontalPodAutoscaler(spec))
+	}
+	if spec.PDB != nil {
+		objects = append(objects, PodDisruptionBudget(spec))
+	}
+	if spec.NetworkPolicy != nil {
+		objects = append(objects, NetworkPolicy(spec))
+	}
+
+	manifests := make([]Manifest, 0, len(objects))
+	for _, obj := range objects {
+		m, err := render(obj)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, nil
+}
+
+// JoinYAML joins manifests into one multi-document YAML stream
+func JoinYAML(manifests []Manifest) string {
+	docs := make([]string, 0, len(manifests))
+	for _, m := range manifests {
+		docs = append(docs, strings.TrimSpace(m.Content))
+	}
+	return strings.Join(docs, "\n---\n") + "\n"
+}
+
+// render marshals an API object to YAML without the empty fields the API
+// types always serialize (status, creationTimestamp)
+func render(obj any) (Manifest, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Manifest{}, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	// Status is written by controllers; some kinds serialize zero counters
+	delete(doc, "status")
+	prune(doc)
+
+	content, err := yaml.Marshal(doc)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to render manifest: %w", err)
+	}
+
+	m := Manifest{Content: string(content)}
+	m.Kind, _ = doc["kind"].(string)
+	m.APIVersion, _ = doc["apiVersion"].(string)
+	if meta, ok := doc["metadata"].(map[string]any); ok {
+		m.Name, _ = meta["name"].(string)
+		m.Namespace, _ = meta["namespace"].(string)
+	}
+	return m, nil
+}
+
+// keepEmpty lists fields where an empty object is meaningful: an empty
+// selector matches everything
+var keepEmpty = map[string]bool{"podSelector": true, "namespaceSelector": true}
+
+// prune removes nulls and empty objects, and reports whether v is empty
+func prune(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case map[string]any:
+		for k, child := range val {
+			if prune(child) && !keepEmpty[k] {
+				delete(val, k)
+			}
+		}
+		return len(val) == 0
+	case []any:
+		for _, child := range val {
+			prune(child)
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package manifestgen
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func fullSpec() AppSpec {
+	return AppSpec{
+		Name:        "api",
+		Namespace:   "shop",
+		Image:       "myorg/api:1.2",
+		Port:        8080,
+		Env:         []EnvVar{{Name: "LOG_LEVEL", Value: "debug"}},
+		Resources:   Resources{CPURequest: "250m", CPULimit: "1", MemoryRequest: "256Mi", MemoryLimit: "512Mi"},
+		Probe:       &ProbeSpec{Path: "/healthz"},
+		ServiceType: "LoadBalancer",
+		Ingress:     &IngressSpec{Host: "api.example.com", ClassName: "nginx", TLSSecret: "api-tls"},
+		Autoscale:   &AutoscaleSpec{MinReplicas: 2, MaxReplicas: 6},
+		PDB:         &PDBSpec{MinAvailable: 1},
+		NetworkPolicy: &NetworkPolicySpec{
+			AllowNamespaces: []string{"ingress-nginx"},
+		},
+	}
+}
+
+func TestBuildRendersEveryObjectStrictly(t *testing.T) {
+	manifests, err := Build(fullSpec())
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	var kinds []string
+	for _, m := range manifests {
+		kinds = append(kinds, m.Kind)
+		if m.Name != "api" || m.Namespace != "shop" {
+			t.Errorf("%s: got %s/%s", m.Kind, m.Namespace, m.Name)
+		}
+		if strings.Contains(m.Content, "status:") || strings.Contains(m.Content, "creationTimestamp") {
+			t.Errorf("%s: empty fields rendered:\n%s", m.Kind, m.Content)
+		}
+
+		// Decoding strictly into the API type proves the YAML matches
+		// the schema
+		var obj any
+		switch m.Kind {
+		case "Deployment":
+			obj = &appsv1.Deployment{}
+		case "Service":
+			obj = &corev1.Service{}
+		case "Ingress":
+			obj = &networkingv1.Ingress{}
+		case "HorizontalPodAutoscaler":
+			obj = &autoscalingv2.HorizontalPodAutoscaler{}
+		case "PodDisruptionBudget":
+			obj = &policyv1.PodDisruptionBudget{}
+		case "NetworkPolicy":
+			obj = &networkingv1.NetworkPolicy{}
+		default:
+			t.Fatalf("unexpected kind %s", m.Kind)
+		}
+		if err := yaml.UnmarshalStrict([]byte(m.Content), obj); err != nil {
+			t.Errorf("%s does not decode strictly: %v", m.Kind, err)
+		}
+	}
+
+	want := "Deployment,Service,Ingress,HorizontalPodAutoscaler,PodDisruptionBudget,NetworkPolicy"
+	if got := strings.Join(kinds, ","); got != want {
+		t.Errorf("kinds = %s, want %s", got, want)
+	}
+}
+
+func TestDeploymentContainer(t *testing.T) {
+	spec := fullSpec()
+	spec.Autoscale = nil
+	spec.Replicas = 3
+	spec.Probe = &ProbeSpec{Readiness: true}
+
+	deployment := Deployment(spec)
+	if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas != 3 {
+		t.Errorf("replicas = %v, want 3", deployment.Spec.Replicas)
+	}
+	if deployment.Spec.Selector.MatchLabels["app"] != "api" {
+		t.Errorf("selector = %v", deployment.Spec.Selector.MatchLabels)
+	}
+
+	c := deployment.Spec.Template.Spec.Containers[0]
+	if c.Resources.Requests.Cpu().String() != "250m" || c.Resources.Limits.Memory().String() != "512Mi" {
+		t.Errorf("resources = %+v", c.Resources)
+	}
+	if len(c.Env) != 1 || c.Env[0].Name != "LOG_LEVEL" || c.Env[0].Value != "debug" {
+		t.Errorf("env = %+v", c.Env)
+	}
+	if c.ReadinessProbe == nil || c.ReadinessProbe.TCPSocket == nil || c.ReadinessProbe.TCPSocket.Port.IntValue() != 8080 {
+		t.Errorf("readiness probe = %+v", c.ReadinessProbe)
+	}
+	if c.LivenessProbe != nil {
+		t.Errorf("liveness probe set, want only readiness")
+	}
+}
+
+func TestPodTemplatePullSecret(t *testing.T) {
+	spec := fullSpec()
+	if secrets := Deployment(spec).Spec.Template.Spec.ImagePullSecrets; len(secrets) != 0 {
+		t.Fatalf("pull secrets = %v, want none", secrets)
+	}
+	spec.PullSecret = "ghcr-io-pull"
+	spec.Kind = KindCronJob
+	spec.Schedule = "0 * * * *"
+	secrets := CronJob(spec).Spec.JobTemplate.Spec.Template.Spec.ImagePullSecrets
+	if len(secrets) != 1 || secrets[0].Name != "ghcr-io-pull" {
+		t.Fatalf("pull secrets = %v", secrets)
+	}
+}
+
+func TestDeploymentLeavesReplicasToAutoscaler(t *testing.T) {
+	spec := AppSpec{Name: "web", Image: "nginx", Port: 80, Autoscale: &AutoscaleSpec{MinReplicas: 2, MaxReplicas: 4}}
+
+	if replicas := Deployment(spec).Spec.Replicas; replicas != nil {
+		t.Errorf("replicas = %d, want unset under an HPA", *replicas)
+	}
+	// The HPA needs a CPU request to compute utilization
+	c := Deployment(spec).Spec.Template.Spec.Containers[0]
+	if c.Resources.Requests.Cpu().String() != "100m" {
+		t.Errorf("cpu request = %s, want the 100m default", c.Resources.Requests.Cpu())
+	}
+	if spec.Autoscale.CPUPercent != 0 {
+		t.Errorf("defaults leaked into the caller's spec")
+	}
+}
+
+func TestNetworkPolicyPeers(t *testing.T) {
+	spec := AppSpec{Name: "db", Image: "postgres", Port: 5432, NetworkPolicy: &NetworkPolicySpec{AllowNamespaces: []string{"api"}}}
+
+	policy := NetworkPolicy(spec)
+	peers := policy.Spec.Ingress[0].From
+	if len(peers) != 2 || peers[0].PodSelector == nil || peers[1].NamespaceSelector.MatchLabels["kubernetes.io/metadata.name"] != "api" {
+		t.Errorf("peers = %+v", peers)
+	}
+
+	// The same-namespace peer must keep its empty selector
+	manifests, err := Build(spec)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if !strings.Contains(manifests[len(manifests)-1].Content, "podSelector: {}") {
+		t.Errorf("empty pod selector pruned:\n%s", manifests[len(manifests)-1].Content)
+	}
+
+	spec.ServiceType = "LoadBalancer"
+	peers = NetworkPolicy(spec).Spec.Ingress[0].From
+	if last := peers[len(peers)-1]; last.IPBlock == nil || last.IPBlock.CIDR != "0.0.0.0/0" {
+		t.Errorf("exposed service should admit outside traffic, peers = %+v", peers)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*AppSpec)
+		want   string
+	}{
+		{"valid", func(*AppSpec) {}, ""},
+		{"bad name", func(s *AppSpec) { s.Name = "My_App" }, "name:"},
+		{"name starting with digit", func(s *AppSpec) { s.Name = "1api" }, "name:"},
+		{"missing image", func(s *AppSpec) { s.Image = "" }, "image is required"},
+		{"bad port", func(s *AppSpec) { s.Port = 70000 }, "port:"},
+		{"bad env name", func(s *AppSpec) { s.Env = []EnvVar{{Name: "1BAD", Value: "x"}} }, "env 1BAD"},
+		{"bad quantity", func(s *AppSpec) { s.Resources.CPURequest = "lots" }, "cpu request"},
+		{"request above limit", func(s *AppSpec) { s.Resources.MemoryRequest = "1Gi" }, "above the limit"},
+		{"bad service type", func(s *AppSpec) { s.ServiceType = "External" }, "service_type"},
+		{"probe without port", func(s *AppSpec) { s.Port = 0; s.Ingress = nil }, "probe: a port is required"},
+		{"bad ingress host", func(s *AppSpec) { s.Ingress.Host = "not a host" }, "ingress host"},
+		{"autoscale max below min", func(s *AppSpec) { s.Autoscale.MaxReplicas = 1 }, "max_replicas 1"},
+		{"pdb blocks drains", func(s *AppSpec) { s.PDB.MinAvailable = 2 }, "node drains block"},
+		{"unknown kind", func(s *AppSpec) { s.Kind = "ReplicaSet" }, "kind:"},
+		{"cronjob without schedule", func(s *AppSpec) { *s = AppSpec{Kind: KindCronJob, Name: "backup", Image: "postgres"} }, "a CronJob needs one"},
+		{"bad schedule", func(s *AppSpec) {
+			*s = AppSpec{Kind: KindCronJob, Name: "backup", Image: "postgres", Schedule: "nightly"}
+		}, "five cron fields"},
+		{"schedule on a deployment", func(s *AppSpec) { s.Schedule = "@daily" }, "only a CronJob"},
+		{"volume on a deployment", func(s *AppSpec) { s.Volume = &VolumeSpec{Size: "1Gi"} }, "only a StatefulSet"},
+		{"bad volume size", func(s *AppSpec) { s.Kind = KindStatefulSet; s.Volume = &VolumeSpec{Size: "big"} }, "volume: size"},
+		{"ingress on a job", func(s *AppSpec) { s.Kind = KindJob }, "ingress: not supported for a Job"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := fullSpec()
+			tt.mutate(&spec)
+			err := spec.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildWorkloadKinds(t *testing.T) {
+	tests := []struct {
+		spec  AppSpec
+		kinds string
+		obj   any
+	}{
+		{AppSpec{Kind: KindStatefulSet, Name: "db", Image: "postgres:16", Port: 5432, Volume: &VolumeSpec{Size: "20Gi", StorageClass: "gp3"}}, "StatefulSet,Service", &appsv1.StatefulSet{}},
+		{AppSpec{Kind: KindJob, Name: "migrate", Image: "myorg/migrate:3", Command: []string{"/bin/sh", "-c", "migrate up"}, Port: 8080}, "Job", &batchv1.Job{}},
+		{AppSpec{Kind: KindCronJob, Name: "backup", Image: "postgres:16", Schedule: "0 2 * * *"}, "CronJob", &batchv1.CronJob{}},
+	}
+	for _, tt := range tests {
+		manifests, err := Build(tt.spec)
+		if err != nil {
+			t.Fatalf("Build(%s): %v", tt.spec.Kind, err)
+		}
+		var kinds []string
+		for _, m := range manifests {
+			kinds = append(kinds, m.Kind)
+		}
+		if got := strings.Join(kinds, ","); got != tt.kinds {
+			t.Errorf("%s kinds = %s, want %s", tt.spec.Kind, got, tt.kinds)
+		}
+		if err := yaml.UnmarshalStrict([]byte(manifests[0].Content), tt.obj); err != nil {
+			t.Errorf("%s does not decode strictly: %v", tt.spec.Kind, err)
+		}
+	}
+
+	sts := StatefulSet(tests[0].spec)
+	claims := sts.Spec.VolumeClaimTemplates
+	if sts.Spec.ServiceName != "db" || len(claims) != 1 || claims[0].Spec.Resources.Requests.Storage().String() != "20Gi" || *claims[0].Spec.StorageClassName != "gp3" {
+		t.Errorf("statefulset spec = %+v", sts.Spec)
+	}
+	if mount := sts.Spec.Template.Spec.Containers[0].VolumeMounts; len(mount) != 1 || mount[0].MountPath != "/var/lib/postgresql/data" {
+		t.Errorf("volume mounts = %+v, want the postgres data directory", mount)
+	}
+	if svc := Service(tests[0].spec); svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		t.Errorf("statefulset service ClusterIP = %q, want headless", svc.Spec.ClusterIP)
+	}
+
+	job := Job(tests[1].spec)
+	if job.Spec.Template.Spec.RestartPolicy != corev1.RestartPolicyOnFailure || len(job.Spec.Template.Spec.Containers[0].Ports) != 0 {
+		t.Errorf("job pod = %+v", job.Spec.Template.Spec)
+	}
+	if cron := CronJob(tests[2].spec); cron.Spec.Schedule != "0 2 * * *" || cron.Spec.ConcurrencyPolicy != batchv1.ForbidConcurrent {
+		t.Errorf("cronjob spec = %+v", cron.Spec)
+	}
+}
+
+func TestJoinYAML(t *testing.T) {
+	manifests, err := Build(AppSpec{Name: "web", Image: "nginx", Port: 80})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	joined := JoinYAML(manifests)
+	if strings.Count(joined, "\n---\n") != 1 || !strings.HasPrefix(joined, "apiVersion: apps/v1") {
+		t.Errorf("joined YAML:\n%s", joined)
+	}
+}
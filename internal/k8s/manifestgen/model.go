@@ -0,0 +1,196 @@
+package manifestgen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/log"
+)
+
+var logger = log.New("k8s.manifestgen")
+
+// AskFunc sends a prompt to a model and returns its reply
+type AskFunc func(ctx context.Context, prompt string) (string, error)
+
+// ParseQueryWithModel reads the spec with the model, for phrasings the
+// patterns miss such as "two copies" or "expose it publicly at shop.example.com".
+// Fields the model leaves empty are filled from ParseQuery, and ParseQuery
+// answers alone when the model fails or its spec does not validate. The
+// image, port and ingress host must appear in the query, so the model
+// cannot invent them.
+func ParseQueryWithModel(ctx context.Context, query string, ask AskFunc) (AppSpec, bool) {
+	fallback, ok := ParseQuery(query)
+	if ask == nil {
+		return fallback, ok
+	}
+
+	response, err := ask(ctx, specPrompt(query))
+	if err != nil {
+		logger.Debugf("spec extraction failed, using patterns: %v", err)
+		return fallback, ok
+	}
+	spec, err := parseModelSpec(query, response)
+	if err != nil {
+		logger.Debugf("spec extraction parse failed, using patterns: %v", err)
+		return fallback, ok
+	}
+
+	merged := mergeSpecs(spec, fallback)
+	if merged.Image == "" {
+		return merged, false
+	}
+	if err := merged.Validate(); err != nil {
+		logger.Debugf("model spec is invalid, using patterns: %v", err)
+		return fallback, ok
+	}
+	return merged, true
+}
+
+func specPrompt(query string) string {
+	return fmt.Sprintf(`Read the application to deploy from this request.
+
+Request: %s
+
+Return a JSON object with only the fields the request states:
+{
+  "kind": "Deployment | StatefulSet | Job | CronJob",
+  "name": "dns-1035-name", "namespace": "default", "image": "repo/image:tag", "replicas": 2, "port": 8080,
+  "command": ["/bin/sh", "-c", "command the request gives"],
+  "schedule": "cron schedule of a CronJob, e.g. 0 2 * * *",
+  "volume": {"size": "20Gi", "mount_path": "/data", "storage_class": "gp3"},
+  "env": [{"name": "KEY", "value": "value"}],
+  "resources": {"cpu_request": "250m", "cpu_limit": "1", "memory_request": "256Mi", "memory_limit": "512Mi"},
+  "probe": {"path": "/healthz", "readiness": true, "liveness": true},
+  "service_type": "ClusterIP | NodePort | LoadBalancer",
+  "ingress": {"host": "app.example.com", "path": "/", "class_name": "nginx"}
+}
+
+Scheduled work ("nightly", "every hour") is a CronJob, work that runs once is a Job, and
+a workload with a persistent volume is a StatefulSet. Never guess an image, host or
+port the request does not give. Return valid JSON only.`, query)
+}
+
+// parseModelSpec reads the model's JSON and drops an image, port or ingress
+// host the query does not mention
+func parseModelSpec(query, response string) (AppSpec, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end <= start {
+		return AppSpec{}, fmt.Errorf("no valid JSON found in response")
+	}
+	var spec AppSpec
+	if err := json.Unmarshal([]byte(response[start:end+1]), &spec); err != nil {
+		return AppSpec{}, fmt.Errorf("invalid spec JSON: %w", err)
+	}
+
+	lower := strings.ToLower(query)
+	spec.Image = strings.ToLower(strings.TrimSpace(spec.Image))
+	if spec.Image != "" && !strings.Contains(lower, imageBaseName(spec.Image)) {
+		logger.Debugf("dropping image %q the query does not name", spec.Image)
+		spec.Image = ""
+	}
+	if spec.Port != 0 && !strings.Contains(lower, strconv.Itoa(int(spec.Port))) {
+		spec.Port = 0
+	}
+	if spec.Ingress != nil && (spec.Ingress.Host == "" || !strings.Contains(lower, strings.ToLower(spec.Ingress.Host))) {
+		spec.Ingress = nil
+	}
+	if spec.Name != "" {
+		spec.Name = sanitizeName(spec.Name)
+	}
+	switch strings.ToLower(spec.Kind) {
+	case "deployment":
+		spec.Kind = KindDeployment
+	case "statefulset":
+		spec.Kind = KindStatefulSet
+	case "job":
+		spec.Kind = KindJob
+	case "cronjob":
+		spec.Kind = KindCronJob
+	default:
+		spec.Kind = ""
+	}
+	switch strings.ToLower(spec.ServiceType) {
+	case "clusterip":
+		spec.ServiceType = "ClusterIP"
+	case "nodeport":
+		spec.ServiceType = "NodePort"
+	case "loadbalancer":
+		spec.ServiceType = "LoadBalancer"
+	default:
+		spec.ServiceType = ""
+	}
+	return spec, nil
+}
+
+// mergeSpecs fills the fields spec leaves empty from fallback
+func mergeSpecs(spec, fallback AppSpec) AppSpec {
+	if spec.Kind == "" {
+		spec.Kind = fallback.Kind
+	}
+	if len(spec.Command) == 0 {
+		spec.Command = fallback.Command
+	}
+	if spec.Schedule == "" {
+		spec.Schedule = fallback.Schedule
+	}
+	if spec.Volume == nil {
+		spec.Volume = fallback.Volume
+	}
+	if spec.Image == "" {
+		spec.Image = fallback.Image
+	}
+	if spec.Name == "" {
+		spec.Name = fallback.Name
+	}
+	if spec.Name == "" && spec.Image != "" {
+		spec.Name = sanitizeName(imageBaseName(spec.Image))
+	}
+	if spec.Namespace == "" {
+		spec.Namespace = fallback.Namespace
+	}
+	if spec.Replicas == 0 {
+		spec.Replicas = fallback.Replicas
+	}
+	if spec.Port == 0 {
+		spec.Port = fallback.Port
+	}
+	if port, ok := knownImagePorts[imageBaseName(spec.Image)]; ok && spec.Port == 0 && !spec.IsBatch() {
+		spec.Port = port
+	}
+	if len(spec.Env) == 0 {
+		spec.Env = fallback.Env
+	}
+	for _, f := range []struct{ dst, src *string }{
+		{&spec.Resources.CPURequest, &fallback.Resources.CPURequest},
+		{&spec.Resources.CPULimit, &fallback.Resources.CPULimit},
+		{&spec.Resources.MemoryRequest, &fallback.Resources.MemoryRequest},
+		{&spec.Resources.MemoryLimit, &fallback.Resources.MemoryLimit},
+	} {
+		if *f.dst == "" {
+			*f.dst = *f.src
+		}
+	}
+	if spec.Probe == nil {
+		spec.Probe = fallback.Probe
+	}
+	if spec.ServiceType == "" {
+		spec.ServiceType = fallback.ServiceType
+	}
+	if spec.Ingress == nil {
+		spec.Ingress = fallback.Ingress
+	}
+	if spec.Autoscale == nil {
+		spec.Autoscale = fallback.Autoscale
+	}
+	if spec.PDB == nil {
+		spec.PDB = fallback.PDB
+	}
+	if spec.NetworkPolicy == nil {
+		spec.NetworkPolicy = fallback.NetworkPolicy
+	}
+	return spec
+}
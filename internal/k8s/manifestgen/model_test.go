@@ -0,0 +1,52 @@
+package manifestgen
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseQueryWithModel(t *testing.T) {
+	query := "deploy myorg/shop:3 with two copies in the store namespace on port 8080, LOG_LEVEL=debug, expose it publicly at shop.example.com"
+	ask := func(context.Context, string) (string, error) {
+		return "```json\n" + `{"image": "myorg/shop:3", "replicas": 2, "service_type": "loadbalancer", "ingress": {"host": "shop.example.com"}}` + "\n```", nil
+	}
+	spec, ok := ParseQueryWithModel(context.Background(), query, ask)
+	if !ok {
+		t.Fatal("ParseQueryWithModel found no image")
+	}
+	// The model reads "two copies"; the patterns fill the port, namespace and env
+	if spec.Replicas != 2 || spec.ServiceType != "LoadBalancer" || spec.Ingress == nil || spec.Ingress.Host != "shop.example.com" {
+		t.Errorf("model fields = replicas %d, service %q, ingress %+v", spec.Replicas, spec.ServiceType, spec.Ingress)
+	}
+	if spec.Name != "shop" || spec.Port != 8080 || spec.Namespace != "store" || len(spec.Env) != 1 {
+		t.Errorf("pattern fields = name %q, port %d, namespace %q, env %+v", spec.Name, spec.Port, spec.Namespace, spec.Env)
+	}
+}
+
+func TestParseQueryWithModelDropsInventedValues(t *testing.T) {
+	ask := func(context.Context, string) (string, error) {
+		return `{"image": "nginx", "port": 80, "ingress": {"host": "app.example.com"}}`, nil
+	}
+	spec, ok := ParseQueryWithModel(context.Background(), "deploy my app with 3 replicas", ask)
+	if ok || spec.Image != "" || spec.Port != 0 || spec.Ingress != nil {
+		t.Errorf("spec = %+v, ok = %v; want no image, port or ingress", spec, ok)
+	}
+}
+
+func TestParseQueryWithModelFallsBack(t *testing.T) {
+	query := "deploy redis with 3 replicas"
+	want, _ := ParseQuery(query)
+	for _, ask := range []AskFunc{
+		nil,
+		func(context.Context, string) (string, error) { return "", errors.New("no model") },
+		func(context.Context, string) (string, error) { return "not json", nil },
+		// An invalid spec falls back whole
+		func(context.Context, string) (string, error) { return `{"resources": {"cpu_request": "lots"}}`, nil },
+	} {
+		spec, ok := ParseQueryWithModel(context.Background(), query, ask)
+		if !ok || spec.Image != want.Image || spec.Replicas != 3 || spec.Resources.CPURequest != "" {
+			t.Errorf("spec = %+v, ok = %v, want the pattern spec", spec, ok)
+		}
+	}
+}
@@ -0,0 +1,485 @@
+package manifestgen
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	imageFlagPattern      = regexp.MustCompile(`(?i)\bimage\s*(?:[=:]\s*|\s+)(\S+)`)
+	imageTokenPattern     = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*(?::[0-9]+)?(?:/[a-z0-9._-]+)*(?::[\w][\w.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+	namePattern           = regexp.MustCompile(`\b(?:called|named|name)\s+([a-z0-9][a-z0-9-]*)`)
+	replicasPattern       = regexp.MustCompile(`\b(\d+)\s+(?:replicas?|instances|copies|pods)\b|\breplicas?\s*(?:=|:|of)?\s*(\d+)\b`)
+	portPattern           = regexp.MustCompile(`\bport\s*(?:=|:)?\s*(\d{1,5})\b|\b(?:listening|listens|serving) on (?:port )?(\d{1,5})\b`)
+	namespacePattern      = regexp.MustCompile(`\b(?:namespace|ns)\s*[=:]?\s+([a-z0-9][a-z0-9-]*)|\bin (?:the )?([a-z0-9][a-z0-9-]*) namespace\b|(?:^|\s)-n\s+([a-z0-9][a-z0-9-]*)`)
+	envPattern            = regexp.MustCompile(`\b([A-Z_][A-Z0-9_]*)=("[^"]*"|'[^']*'|[^\s,;]+)`)
+	probePathPattern      = regexp.MustCompile(`(?i)\b(?:health\s*checks?|healthchecks?|probes?|health endpoint)\s+(?:on\s+|at\s+|path\s+|to\s+)?(/[A-Za-z0-9._~/-]*)`)
+	hostPattern           = regexp.MustCompile(`\b(?:host|hostname|domain|at|on)\s+((?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,})\b`)
+	ingressClassPattern   = regexp.MustCompile(`\b(nginx|traefik|alb|gce|haproxy)\s+ingress\b|\bingress\s*class\s*[=:]?\s*([a-z0-9-]+)`)
+	rangePattern          = regexp.MustCompile(`(?:autoscal\w*|hpa|scale)\D{0,20}?(\d+)\s*(?:-|to|and)\s*(\d+)`)
+	minPattern            = regexp.MustCompile(`\bmin(?:imum)?\s*(?:replicas?\s*)?(?:of\s+)?[=:]?\s*(\d+)\b`)
+	maxPattern            = regexp.MustCompile(`\bmax(?:imum)?\s*(?:replicas?\s*)?(?:of\s+)?[=:]?\s*(\d+)\b`)
+	cpuTargetPattern      = regexp.MustCompile(`\b(\d{1,3})\s*%`)
+	minAvailablePattern   = regexp.MustCompile(`\bmin(?:imum)?[\s_-]*available\s*(?:of\s+)?[=:]?\s*(\d+)\b`)
+	allowNamespacePattern = regexp.MustCompile(`\bfrom (?:the )?([a-z0-9][a-z0-9-]*) namespace\b`)
+	commandPattern        = regexp.MustCompile("`([^`]+)`|\\b(?:command|runs?|running|executes?|executing)\\s*[=:]?\\s*[\"']([^\"']+)[\"']")
+	jobPattern            = regexp.MustCompile(`\b(?:jobs?|batch|one-off|one-shot|run once|to completion)\b`)
+	cronPattern           = regexp.MustCompile(`\b(?:schedule|cron)\s*[=:]?\s*["']([^"']+)["']`)
+	everyPattern          = regexp.MustCompile(`\bevery\s+(\d+\s+)?(minute|hour|day|night|week|month)s?\b`)
+	periodPattern         = regexp.MustCompile(`(?:^|\s)(nightly|daily|hourly|weekly|monthly)(?:$|[\s.,;])`)
+	atHourPattern         = regexp.MustCompile(`\bat\s+(\d{1,2})(?::(\d{2}))?\s*(am|pm)?\b`)
+	volumePattern         = regexp.MustCompile(`\b(\d+(?:\.\d+)?\s*(?:ti|tb|t|gi|gb|g|mi|mb))\s+(?:persistent\s+)?(?:volume|disk|pvc|storage)\b|\b(?:volume|disk|pvc|storage)\s+(?:of\s+|size\s+)?(\d+(?:\.\d+)?\s*(?:ti|tb|t|gi|gb|g|mi|mb))\b`)
+	mountPattern          = regexp.MustCompile(`\bmount(?:ed)?\s+(?:at|on)\s+(/[A-Za-z0-9._/-]*)`)
+	storageClassPattern   = regexp.MustCompile(`\bstorage\s*class\s*[=:]?\s*([a-z0-9][a-z0-9.-]*)`)
+)
+
+// Resource phrases, matched in this order with each match masked out so a
+// later, looser pattern cannot read the same words again
+const (
+	quantityExpr = `(\d+(?:\.\d+)?\s*(?:cores?|mi|gi|ki|mb|gb|m|g|k)?)\b`
+	resourceExpr = `(cpu|memory|mem|ram)`
+	kindExpr     = `(request|limit)s?`
+)
+
+var (
+	// "limits cpu=500m,memory=512mi"
+	resourceListPattern = regexp.MustCompile(`\b` + kindExpr + `\s*[=:]?\s*((?:` + resourceExpr + `\s*[=:]\s*\d+(?:\.\d+)?[a-z]*[,\s]*)+)`)
+	resourceItemPattern = regexp.MustCompile(resourceExpr + `\s*[=:]\s*(\d+(?:\.\d+)?[a-z]*)`)
+	// "cpu limit 500m", "memory request of 256mi"
+	resourceKindQtyPattern = regexp.MustCompile(`\b` + resourceExpr + `\s+` + kindExpr + `\s*(?:of|=|:|to)?\s*` + quantityExpr)
+	// "limit 1gi memory", "request of 250m cpu"
+	kindQtyResourcePattern = regexp.MustCompile(`\b` + kindExpr + `\s+(?:of\s+)?` + quantityExpr + `\s+(?:of\s+)?` + resourceExpr + `\b`)
+	// "500m cpu limit"
+	qtyResourceKindPattern = regexp.MustCompile(`\b` + quantityExpr + `\s+` + resourceExpr + `\s+` + kindExpr)
+	// "256mi memory", "memory=256mi": read as requests
+	qtyResourcePattern = regexp.MustCompile(`\b` + quantityExpr + `\s+(?:of\s+)?` + resourceExpr + `\b`)
+	resourceQtyPattern = regexp.MustCompile(`\b` + resourceExpr + `\s*[=:]\s*` + quantityExpr)
+)
+
+// resourceNames are never environment variables, so "CPU=500m" stays a
+// resource request
+var resourceNames = map[string]bool{"cpu": true, "memory": true, "mem": true, "ram": true}
+
+// portlessImages are well known base images that serve no default port
+var portlessImages = map[string]bool{"node": true, "python": true, "golang": true, "busybox": true, "alpine": true}
+
+// imageStopWords follow "image" in prose ("the image called web") without
+// naming one
+var imageStopWords = map[string]bool{"called": true, "named": true, "with": true, "from": true, "to": true, "for": true, "on": true, "in": true, "and": true}
+
+// knownImagePorts are the ports of well known images, used when the query
+// does not name one
+var knownImagePorts = map[string]int32{
+	"nginx":         80,
+	"httpd":         80,
+	"apache":        80,
+	"caddy":         80,
+	"redis":         6379,
+	"postgres":      5432,
+	"mysql":         3306,
+	"mariadb":       3306,
+	"mongo":         27017,
+	"rabbitmq":      5672,
+	"memcached":     11211,
+	"grafana":       3000,
+	"prometheus":    9090,
+	"elasticsearch": 9200,
+}
+
+// ParseQuery reads an application spec from a natural-language deploy
+// request such as "deploy myorg/api:1.2 called api with 3 replicas on port
+// 8080, cpu request 250m, memory limit 512mi, LOG_LEVEL=debug and a health
+// check on /healthz". It reports whether an image was found; the other
+// fields are left empty when the query does not mention them.
+func ParseQuery(query string) (AppSpec, bool) {
+	var spec AppSpec
+
+	// The command runs as written; the rest of the query is read without
+	// it, so its paths and flags are not taken for an image or env var
+	if m := commandPattern.FindStringSubmatch(query); m != nil {
+		spec.Command = []string{"/bin/sh", "-c", strings.TrimSpace(firstNonEmpty(m[1:]...))}
+		query = strings.Replace(query, m[0], " ", 1)
+	}
+	lower := strings.ToLower(query)
+
+	if m := namePattern.FindStringSubmatch(lower); m != nil {
+		spec.Name = sanitizeName(m[1])
+	}
+	if m := replicasPattern.FindStringSubmatch(lower); m != nil {
+		spec.Replicas = atoi32(firstNonEmpty(m[1:]...))
+	}
+	if m := portPattern.FindStringSubmatch(lower); m != nil {
+		spec.Port = atoi32(firstNonEmpty(m[1:]...))
+	}
+	if image := parseImage(query, lower); image != "" {
+		spec.SetImage(image)
+	}
+	if m := namespacePattern.FindStringSubmatch(lower); m != nil {
+		spec.Namespace = firstNonEmpty(m[1:]...)
+	}
+
+	for _, m := range envPattern.FindAllStringSubmatch(query, -1) {
+		if resourceNames[strings.ToLower(m[1])] {
+			continue
+		}
+		spec.Env = append(spec.Env, EnvVar{Name: m[1], Value: strings.Trim(m[2], `"'`)})
+	}
+
+	spec.Resources = parseResources(lower)
+	spec.Probe = parseProbe(query, lower)
+
+	switch {
+	case strings.Contains(lower, "nodeport"):
+		spec.ServiceType = "NodePort"
+	case strings.Contains(lower, "loadbalancer"), strings.Contains(lower, "load balancer"):
+		spec.ServiceType = "LoadBalancer"
+	case strings.Contains(lower, "clusterip"), strings.Contains(lower, "internal service"):
+		spec.ServiceType = "ClusterIP"
+	}
+
+	wantsIngress := strings.Contains(lower, "ingress") || strings.Contains(lower, "host") || strings.Contains(lower, "domain")
+	if m := hostPattern.FindStringSubmatch(lower); m != nil && wantsIngress && !strings.HasPrefix(spec.Image, m[1]) {
+		spec.Ingress = &IngressSpec{Host: m[1]}
+		if c := ingressClassPattern.FindStringSubmatch(lower); c != nil {
+			spec.Ingress.ClassName = firstNonEmpty(c[1:]...)
+		}
+		if strings.Contains(lower, "tls") || strings.Contains(lower, "https") {
+			spec.Ingress.TLSSecret = spec.Name + "-tls"
+		}
+	}
+
+	if strings.Contains(lower, "autoscal") || strings.Contains(lower, "hpa") {
+		spec.Autoscale = parseAutoscale(lower, spec.Replicas)
+	}
+
+	if strings.Contains(lower, "pdb") || strings.Contains(lower, "disruption budget") || strings.Contains(lower, "highly available") {
+		spec.PDB = &PDBSpec{MinAvailable: 1}
+		if m := minAvailablePattern.FindStringSubmatch(lower); m != nil {
+			spec.PDB.MinAvailable = atoi32(m[1])
+		}
+		// A budget needs a spare pod to let nodes drain
+		if spec.Replicas == 0 && spec.Autoscale == nil {
+			spec.Replicas = spec.PDB.MinAvailable + 1
+		}
+	}
+
+	if strings.Contains(lower, "network policy") || strings.Contains(lower, "networkpolicy") || strings.Contains(lower, "restrict traffic") || strings.Contains(lower, "isolate") {
+		spec.NetworkPolicy = &NetworkPolicySpec{}
+		for _, m := range allowNamespacePattern.FindAllStringSubmatch(lower, -1) {
+			if m[1] != spec.Namespace {
+				spec.NetworkPolicy.AllowNamespaces = append(spec.NetworkPolicy.AllowNamespaces, m[1])
+			}
+		}
+	}
+
+	spec.Kind, spec.Schedule = parseKind(lower)
+	if m := volumePattern.FindStringSubmatch(lower); m != nil {
+		if size := normalizeQuantity(firstNonEmpty(m[1:]...), false); size != "" {
+			spec.Volume = &VolumeSpec{Size: size}
+			if m := mountPattern.FindStringSubmatch(query); m != nil {
+				spec.Volume.MountPath = strings.TrimRight(m[1], ".,;")
+			}
+			if m := storageClassPattern.FindStringSubmatch(lower); m != nil {
+				spec.Volume.StorageClass = m[1]
+			}
+			// A Deployment's pods would share one claim, so persistent
+			// storage asks for a StatefulSet
+			if spec.Kind == "" {
+				spec.Kind = KindStatefulSet
+			}
+		}
+	}
+	if spec.IsBatch() {
+		spec.Port, spec.Probe = 0, nil
+	}
+
+	return spec, spec.Image != ""
+}
+
+// parseKind reads the workload kind, and the schedule of a CronJob. A
+// schedule such as "nightly" or "every 15 minutes" makes a CronJob unless
+// the query asks for a serving kind.
+func parseKind(lower string) (string, string) {
+	if strings.Contains(lower, "statefulset") || strings.Contains(lower, "stateful set") {
+		return KindStatefulSet, ""
+	}
+	schedule := parseSchedule(lower)
+	switch {
+	case schedule != "" && !strings.Contains(lower, "deployment"):
+		return KindCronJob, schedule
+	case strings.Contains(lower, "cronjob") || strings.Contains(lower, "cron job"):
+		// A CronJob without a schedule fails validation and says so
+		return KindCronJob, ""
+	case jobPattern.MatchString(lower):
+		return KindJob, ""
+	}
+	return "", ""
+}
+
+// parseSchedule turns "nightly at 2am", "every 15 minutes", "hourly" or an
+// explicit "schedule '*/5 * * * *'" into a cron schedule
+func parseSchedule(lower string) string {
+	if m := cronPattern.FindStringSubmatch(lower); m != nil && validSchedule(strings.TrimSpace(m[1])) {
+		return strings.TrimSpace(m[1])
+	}
+
+	period, count := "", "1"
+	if m := everyPattern.FindStringSubmatch(lower); m != nil {
+		period = m[2]
+		if n := strings.TrimSpace(m[1]); n != "" {
+			count = n
+		}
+	} else if m := periodPattern.FindStringSubmatch(lower); m != nil {
+		period = map[string]string{"nightly": "day", "daily": "day", "hourly": "hour", "weekly": "week", "monthly": "month"}[m[1]]
+	}
+
+	minute, hour := "0", "0"
+	if m := atHourPattern.FindStringSubmatch(lower); m != nil {
+		h := atoi32(m[1])
+		if m[3] == "pm" && h < 12 {
+			h += 12
+		} else if m[3] == "am" && h == 12 {
+			h = 0
+		}
+		if h < 24 {
+			hour = strconv.Itoa(int(h))
+			if m[2] != "" && atoi32(m[2]) < 60 {
+				minute = strconv.Itoa(int(atoi32(m[2])))
+			}
+		}
+	}
+
+	switch period {
+	case "minute":
+		if count == "1" {
+			return "* * * * *"
+		}
+		return "*/" + count + " * * * *"
+	case "hour":
+		if count == "1" {
+			return "0 * * * *"
+		}
+		return "0 */" + count + " * * *"
+	case "day", "night":
+		if count == "1" {
+			return minute + " " + hour + " * * *"
+		}
+		return minute + " " + hour + " */" + count + " * *"
+	case "week":
+		return minute + " " + hour + " * * 0"
+	case "month":
+		return minute + " " + hour + " 1 * *"
+	}
+	return ""
+}
+
+// parseImage prefers an explicit "image X", then any token shaped like an
+// image reference with a registry, path or tag, then a well known image name
+func parseImage(query, lower string) string {
+	if m := imageFlagPattern.FindStringSubmatch(query); m != nil {
+		if image := strings.ToLower(cleanToken(m[1])); imageTokenPattern.MatchString(image) && !imageStopWords[image] {
+			return image
+		}
+	}
+	for _, token := range strings.Fields(lower) {
+		token = cleanToken(token)
+		if strings.HasPrefix(token, "/") || strings.Contains(token, "=") || !strings.ContainsAny(token, "/:") {
+			continue
+		}
+		if imageTokenPattern.MatchString(token) {
+			return token
+		}
+	}
+	for _, token := range strings.Fields(lower) {
+		token = cleanToken(token)
+		if _, ok := knownImagePorts[token]; ok || portlessImages[token] {
+			return token
+		}
+	}
+	return ""
+}
+
+func parseResources(lower string) Resources {
+	var res Resources
+	text := lower
+	mask := func(pattern *regexp.Regexp, apply func(m []string)) {
+		for _, loc := range pattern.FindAllStringSubmatchIndex(text, -1) {
+			m := make([]string, len(loc)/2)
+			for i := range m {
+				if loc[2*i] >= 0 {
+					m[i] = text[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			apply(m)
+		}
+		text = pattern.ReplaceAllStringFunc(text, func(s string) string { return strings.Repeat(" ", len(s)) })
+	}
+	set := func(kind, name, qty string) {
+		isCPU := name == "cpu"
+		qty = normalizeQuantity(qty, isCPU)
+		if qty == "" {
+			return
+		}
+		var field *string
+		switch {
+		case isCPU && kind == "limit":
+			field = &res.CPULimit
+		case isCPU:
+			field = &res.CPURequest
+		case kind == "limit":
+			field = &res.MemoryLimit
+		default:
+			field = &res.MemoryRequest
+		}
+		if *field == "" {
+			*field = qty
+		}
+	}
+
+	mask(resourceListPattern, func(m []string) {
+		for _, item := range resourceItemPattern.FindAllStringSubmatch(m[2], -1) {
+			set(m[1], item[1], item[2])
+		}
+	})
+	mask(resourceKindQtyPattern, func(m []string) { set(m[2], m[1], m[3]) })
+	mask(kindQtyResourcePattern, func(m []string) { set(m[1], m[3], m[2]) })
+	mask(qtyResourceKindPattern, func(m []string) { set(m[3], m[2], m[1]) })
+	mask(qtyResourcePattern, func(m []string) { set("request", m[2], m[1]) })
+	mask(resourceQtyPattern, func(m []string) { set("request", m[1], m[2]) })
+	return res
+}
+
+// normalizeQuantity turns loose units ("2 cores", "512mb", "1g") into
+// Kubernetes quantities. Memory without a unit is read as MiB, since nobody
+// asks for 512 bytes.
+func normalizeQuantity(qty string, isCPU bool) string {
+	qty = strings.ReplaceAll(strings.TrimSpace(qty), " ", "")
+	i := 0
+	for i < len(qty) && (qty[i] == '.' || (qty[i] >= '0' && qty[i] <= '9')) {
+		i++
+	}
+	number, unit := qty[:i], qty[i:]
+	if number == "" {
+		return ""
+	}
+	if isCPU {
+		switch unit {
+		case "", "core", "cores":
+			return number
+		case "m":
+			return number + "m"
+		}
+		return ""
+	}
+	switch unit {
+	case "", "m", "mi", "mb":
+		return number + "Mi"
+	case "g", "gi", "gb":
+		return number + "Gi"
+	case "t", "ti", "tb":
+		return number + "Ti"
+	case "k", "ki":
+		return number + "Ki"
+	}
+	return ""
+}
+
+func parseProbe(query, lower string) *ProbeSpec {
+	readiness := strings.Contains(lower, "readiness")
+	liveness := strings.Contains(lower, "liveness")
+	if !readiness && !liveness && !strings.Contains(lower, "probe") &&
+		!strings.Contains(lower, "health check") && !strings.Contains(lower, "healthcheck") && !strings.Contains(lower, "health endpoint") {
+		return nil
+	}
+	probe := &ProbeSpec{Readiness: readiness, Liveness: liveness}
+	if m := probePathPattern.FindStringSubmatch(query); m != nil {
+		probe.Path = strings.TrimRight(m[1], ".,;")
+	}
+	return probe
+}
+
+func parseAutoscale(lower string, replicas int32) *AutoscaleSpec {
+	as := &AutoscaleSpec{}
+	if m := rangePattern.FindStringSubmatch(lower); m != nil {
+		as.MinReplicas, as.MaxReplicas = atoi32(m[1]), atoi32(m[2])
+	}
+	if m := minPattern.FindStringSubmatch(lower); m != nil {
+		as.MinReplicas = atoi32(m[1])
+	}
+	if m := maxPattern.FindStringSubmatch(lower); m != nil {
+		as.MaxReplicas = atoi32(m[1])
+	}
+	if m := cpuTargetPattern.FindStringSubmatch(lower); m != nil {
+		as.CPUPercent = atoi32(m[1])
+	}
+	if as.MinReplicas == 0 {
+		as.MinReplicas = max(replicas, 1)
+	}
+	if as.MaxReplicas == 0 {
+		as.MaxReplicas = max(as.MinReplicas*3, 3)
+	}
+	return as
+}
+
+// SetImage sets the container image, and the name and port that follow
+// from it when they are still empty: "myorg/api:1.2" is named api, and
+// well known images such as redis get their usual port
+func (s *AppSpec) SetImage(image string) {
+	s.Image = image
+	if s.Name == "" {
+		s.Name = sanitizeName(imageBaseName(image))
+	}
+	if port, ok := knownImagePorts[imageBaseName(image)]; ok && s.Port == 0 {
+		s.Port = port
+	}
+}
+
+func imageBaseName(image string) string {
+	base := path.Base(image)
+	if i := strings.IndexAny(base, ":@"); i > 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+// sanitizeName turns an image or user supplied name into a DNS-1035 label
+func sanitizeName(name string) string {
+	name = strings.ToLower(name)
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+	name = strings.Trim(name, "-")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "app-" + name
+	}
+	if len(name) > 63 {
+		name = strings.TrimRight(name[:63], "-")
+	}
+	return name
+}
+
+func cleanToken(token string) string {
+	return strings.Trim(token, ".,;!?()'\"`")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func atoi32(s string) int32 {
+	n, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return int32(n)
+}
@@ -0,0 +1,208 @@
+package manifestgen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQueryBasics(t *testing.T) {
+	tests := []struct {
+		query     string
+		image     string
+		name      string
+		replicas  int32
+		port      int32
+		namespace string
+	}{
+		{"deploy nginx", "nginx", "nginx", 0, 80, ""},
+		{"deploy redis with 3 replicas in the cache namespace", "redis", "redis", 3, 6379, "cache"},
+		{"deploy myorg/api:1.2 called storefront on port 8080 -n shop", "myorg/api:1.2", "storefront", 0, 8080, "shop"},
+		{"run image ghcr.io/acme/worker@sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef" + " replicas=2", "ghcr.io/acme/worker@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef", "worker", 2, 0, ""},
+		{"deploy 5 copies of registry.local:5000/team/web_app:v2 namespace staging", "registry.local:5000/team/web_app:v2", "web-app", 5, 0, "staging"},
+		{"deploy something to kubernetes", "", "", 0, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			spec, ok := ParseQuery(tt.query)
+			if ok != (tt.image != "") {
+				t.Errorf("ok = %v", ok)
+			}
+			if spec.Image != tt.image || spec.Name != tt.name || spec.Replicas != tt.replicas || spec.Port != tt.port || spec.Namespace != tt.namespace {
+				t.Errorf("got image=%q name=%q replicas=%d port=%d ns=%q", spec.Image, spec.Name, spec.Replicas, spec.Port, spec.Namespace)
+			}
+		})
+	}
+}
+
+func TestSetImage(t *testing.T) {
+	spec, _ := ParseQuery("deploy something called shop on port 8080")
+	spec.SetImage("redis:7")
+	if spec.Image != "redis:7" || spec.Name != "shop" || spec.Port != 8080 {
+		t.Errorf("SetImage on a named spec = %+v, want redis:7 named shop on 8080", spec)
+	}
+
+	var bare AppSpec
+	bare.SetImage("docker.io/library/redis:7")
+	if bare.Name != "redis" || bare.Port != 6379 {
+		t.Errorf("SetImage defaults = name %q port %d, want redis 6379", bare.Name, bare.Port)
+	}
+}
+
+func TestParseQueryEnv(t *testing.T) {
+	spec, _ := ParseQuery(`deploy myorg/api:1 with LOG_LEVEL=debug, DB_URL="postgres://db:5432/app" and CPU=500m`)
+
+	want := []EnvVar{{Name: "LOG_LEVEL", Value: "debug"}, {Name: "DB_URL", Value: "postgres://db:5432/app"}}
+	if !reflect.DeepEqual(spec.Env, want) {
+		t.Errorf("env = %+v, want %+v", spec.Env, want)
+	}
+	if spec.Resources.CPURequest != "500m" {
+		t.Errorf("cpu request = %q, want 500m", spec.Resources.CPURequest)
+	}
+}
+
+func TestParseQueryResources(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Resources
+	}{
+		{"cpu request 250m and memory limit 512mi", Resources{CPURequest: "250m", MemoryLimit: "512Mi"}},
+		{"requests cpu=100m,memory=128mi limits cpu=1,memory=1gi", Resources{CPURequest: "100m", MemoryRequest: "128Mi", CPULimit: "1", MemoryLimit: "1Gi"}},
+		{"500m cpu limit and a request of 256mb memory", Resources{CPULimit: "500m", MemoryRequest: "256Mi"}},
+		{"with 2 cores cpu and 4g memory", Resources{CPURequest: "2", MemoryRequest: "4Gi"}},
+		{"memory limit of 1.5gi", Resources{MemoryLimit: "1.5Gi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			spec, _ := ParseQuery("deploy nginx " + tt.query)
+			if spec.Resources != tt.want {
+				t.Errorf("resources = %+v, want %+v", spec.Resources, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryProbes(t *testing.T) {
+	tests := []struct {
+		query string
+		want  *ProbeSpec
+	}{
+		{"deploy nginx", nil},
+		{"deploy nginx with a health check on /healthz", &ProbeSpec{Path: "/healthz"}},
+		{"deploy nginx with a readiness probe at /ready.", &ProbeSpec{Path: "/ready", Readiness: true}},
+		{"deploy redis with a liveness probe", &ProbeSpec{Liveness: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			spec, _ := ParseQuery(tt.query)
+			if !reflect.DeepEqual(spec.Probe, tt.want) {
+				t.Errorf("probe = %+v, want %+v", spec.Probe, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryExposure(t *testing.T) {
+	spec, _ := ParseQuery("deploy ghcr.io/acme/shop:3 with nginx ingress on host shop.example.com using tls")
+	if spec.Image != "ghcr.io/acme/shop:3" {
+		t.Errorf("image = %q", spec.Image)
+	}
+	want := &IngressSpec{Host: "shop.example.com", ClassName: "nginx", TLSSecret: "shop-tls"}
+	if !reflect.DeepEqual(spec.Ingress, want) {
+		t.Errorf("ingress = %+v, want %+v", spec.Ingress, want)
+	}
+
+	// A registry host is not an ingress host
+	spec, _ = ParseQuery("deploy the image on ghcr.io/acme/shop:3 as a nodeport")
+	if spec.Ingress != nil || spec.ServiceType != "NodePort" {
+		t.Errorf("ingress = %+v, service type = %q", spec.Ingress, spec.ServiceType)
+	}
+}
+
+func TestParseQueryAvailability(t *testing.T) {
+	spec, _ := ParseQuery("deploy myorg/api:1 with autoscaling from 2 to 8 at 70% cpu and a pdb")
+	if !reflect.DeepEqual(spec.Autoscale, &AutoscaleSpec{MinReplicas: 2, MaxReplicas: 8, CPUPercent: 70}) {
+		t.Errorf("autoscale = %+v", spec.Autoscale)
+	}
+	if !reflect.DeepEqual(spec.PDB, &PDBSpec{MinAvailable: 1}) {
+		t.Errorf("pdb = %+v", spec.PDB)
+	}
+	if err := spec.Validate(); err != nil {
+		t.Errorf("parsed spec is invalid: %v", err)
+	}
+
+	// A budget on its own gets a spare replica so drains can proceed
+	spec, _ = ParseQuery("deploy nginx with a disruption budget")
+	if spec.Replicas != 2 || spec.Validate() != nil {
+		t.Errorf("replicas = %d, validate = %v", spec.Replicas, spec.Validate())
+	}
+
+	spec, _ = ParseQuery("deploy nginx with 2 replicas and hpa")
+	if !reflect.DeepEqual(spec.Autoscale, &AutoscaleSpec{MinReplicas: 2, MaxReplicas: 6}) {
+		t.Errorf("autoscale defaults = %+v", spec.Autoscale)
+	}
+}
+
+func TestParseQueryNetworkPolicy(t *testing.T) {
+	spec, _ := ParseQuery("deploy postgres in the data namespace with a network policy allowing traffic from the api namespace")
+	if spec.NetworkPolicy == nil || !reflect.DeepEqual(spec.NetworkPolicy.AllowNamespaces, []string{"api"}) {
+		t.Errorf("network policy = %+v", spec.NetworkPolicy)
+	}
+	if spec.Namespace != "data" {
+		t.Errorf("namespace = %q", spec.Namespace)
+	}
+}
+
+func TestParseQueryWorkloadKinds(t *testing.T) {
+	spec, ok := ParseQuery("deploy postgres as a statefulset with a 20Gi volume, storage class gp3")
+	if !ok || spec.Kind != KindStatefulSet || spec.Port != 5432 {
+		t.Fatalf("statefulset = %+v", spec)
+	}
+	if spec.Volume == nil || spec.Volume.Size != "20Gi" || spec.Volume.StorageClass != "gp3" || spec.Volume.MountPath != "" {
+		t.Errorf("volume = %+v", spec.Volume)
+	}
+
+	spec, _ = ParseQuery("run a nightly job at 2:30am that backs up orders with image postgres:16 running `pg_dump -h orders-db -f /backup/orders.sql`")
+	if spec.Kind != KindCronJob || spec.Schedule != "30 2 * * *" || spec.Image != "postgres:16" || spec.Port != 0 {
+		t.Errorf("cronjob = %+v", spec)
+	}
+	if len(spec.Command) != 3 || spec.Command[2] != "pg_dump -h orders-db -f /backup/orders.sql" {
+		t.Errorf("command = %q", spec.Command)
+	}
+
+	spec, _ = ParseQuery("run a one-off job with image myorg/migrate:3 command 'migrate up'")
+	if spec.Kind != KindJob || spec.Schedule != "" || spec.Command[2] != "migrate up" {
+		t.Errorf("job = %+v", spec)
+	}
+
+	// A volume alone asks for a StatefulSet, and serving kinds stay unset
+	if spec, _ := ParseQuery("deploy redis with a 5gi disk mounted at /var/redis"); spec.Kind != KindStatefulSet || spec.Volume.MountPath != "/var/redis" {
+		t.Errorf("volume spec = %+v", spec)
+	}
+	if spec, _ := ParseQuery("deploy nginx with 3 replicas"); spec.Kind != "" {
+		t.Errorf("kind = %q, want the Deployment default", spec.Kind)
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	for query, want := range map[string]string{
+		"every 15 minutes":            "*/15 * * * *",
+		"every minute":                "* * * * *",
+		"hourly":                      "0 * * * *",
+		"every 6 hours":               "0 */6 * * *",
+		"nightly":                     "0 0 * * *",
+		"daily at 9pm":                "0 21 * * *",
+		"every 2 days at 12am":        "0 0 */2 * *",
+		"weekly":                      "0 0 * * 0",
+		"monthly at 3":                "0 3 1 * *",
+		"on schedule '*/5 * * * 1-5'": "*/5 * * * 1-5",
+		"on schedule 'whenever'":      "",
+		"the daily-report app":        "",
+	} {
+		if got := parseSchedule(query); got != want {
+			t.Errorf("parseSchedule(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 )
 
@@ -189,16 +190,16 @@ metadata:
 	// Add annotations if any
 	if len(opts.Annotations) > 0 {
 		manifest += "\n  annotations:"
-		for k, v := range opts.Annotations {
-			manifest += fmt.Sprintf("\n    %s: %q", k, v)
+		for _, k := range sortedKeys(opts.Annotations) {
+			manifest += fmt.Sprintf("\n    %s: %q", k, opts.Annotations[k])
 		}
 	}
 
 	// Add labels if any
 	if len(opts.Labels) > 0 {
 		manifest += "\n  labels:"
-		for k, v := range opts.Labels {
-			manifest += fmt.Sprintf("\n    %s: %q", k, v)
+		for _, k := range sortedKeys(opts.Labels) {
+			manifest += fmt.Sprintf("\n    %s: %q", k, opts.Labels[k])
 		}
 	}
 
@@ -217,7 +218,10 @@ metadata:
 			for _, host := range tls.Hosts {
 				manifest += fmt.Sprintf("\n    - %s", host)
 			}
-			manifest += fmt.Sprintf("\n    secretName: %s", tls.SecretName)
+			// ALB takes certificates from ACM rather than a secret
+			if tls.SecretName != "" {
+				manifest += fmt.Sprintf("\n    secretName: %s", tls.SecretName)
+			}
 		}
 	}
 
@@ -256,6 +260,16 @@ metadata:
 	return manifest
 }
 
+// sortedKeys returns a map's keys in order, so generated manifests are stable
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // buildRulePatch builds a JSON patch for adding a rule
 func (m *IngressManager) buildRulePatch(rule IngressRuleSpec) string {
 	paths := make([]map[string]interface{}, 0, len(rule.Paths))
@@ -334,9 +348,7 @@ func (m *IngressManager) parseIngressList(data []byte) ([]IngressInfo, error) {
 	for _, item := range list.Items {
 		ing, err := m.parseIngress(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[ingress] failed to parse ingress: %v\n", err)
-			}
+			logger.Debugf("failed to parse ingress: %v", err)
 			continue
 		}
 		ingresses = append(ingresses, *ing)
@@ -0,0 +1,406 @@
+package networking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Ingress controller kinds the generated annotations are tailored to
+const (
+	ControllerNginx   = "nginx"
+	ControllerTraefik = "traefik"
+	ControllerALB     = "alb"
+	ControllerOther   = "other"
+)
+
+// IngressController is an ingress controller installed in the cluster
+type IngressController struct {
+	Kind       string `json:"kind"`                 // nginx, traefik, alb or other
+	ClassName  string `json:"className,omitempty"`  // IngressClass to set as ingressClassName
+	Controller string `json:"controller,omitempty"` // spec.controller of the IngressClass
+	Default    bool   `json:"default,omitempty"`    // marked as the cluster's default class
+}
+
+// controllerKinds maps IngressClass spec.controller values to kinds
+var controllerKinds = map[string]string{
+	"k8s.io/ingress-nginx":          ControllerNginx,
+	"nginx.org/ingress-controller":  ControllerNginx,
+	"traefik.io/ingress-controller": ControllerTraefik,
+	"ingress.k8s.aws/alb":           ControllerALB,
+}
+
+// controllerDeployments identifies controllers installed without an
+// IngressClass by the app.kubernetes.io/name of their deployment
+var controllerDeployments = map[string]string{
+	"ingress-nginx":                ControllerNginx,
+	"nginx-ingress":                ControllerNginx,
+	"traefik":                      ControllerTraefik,
+	"aws-load-balancer-controller": ControllerALB,
+}
+
+// DetectIngressControllers lists the ingress controllers in the cluster from
+// their IngressClasses, falling back to well-known controller deployments
+// for clusters that predate IngressClass
+func (m *IngressManager) DetectIngressControllers(ctx context.Context) ([]IngressController, error) {
+	out, err := m.client.Run(ctx, "get", "ingressclasses", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingress classes: %w", err)
+	}
+	var classes struct {
+		Items []struct {
+			Metadata struct {
+				Name        string            `json:"name"`
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+			Spec struct {
+				Controller string `json:"controller"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &classes); err != nil {
+		return nil, fmt.Errorf("failed to parse ingress classes: %w", err)
+	}
+	var controllers []IngressController
+	for _, c := range classes.Items {
+		kind, ok := controllerKinds[c.Spec.Controller]
+		if !ok {
+			kind = ControllerOther
+		}
+		controllers = append(controllers, IngressController{
+			Kind:       kind,
+			ClassName:  c.Metadata.Name,
+			Controller: c.Spec.Controller,
+			Default:    c.Metadata.Annotations["ingressclass.kubernetes.io/is-default-class"] == "true",
+		})
+	}
+	if len(controllers) > 0 {
+		return controllers, nil
+	}
+
+	out, err = m.client.Run(ctx, "get", "deployments", "-A", "-l", "app.kubernetes.io/name", "-o", "json")
+	if err != nil {
+		return nil, nil
+	}
+	var deployments struct {
+		Items []struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if json.Unmarshal([]byte(out), &deployments) != nil {
+		return nil, nil
+	}
+	for _, d := range deployments.Items {
+		if kind, ok := controllerDeployments[d.Metadata.Labels["app.kubernetes.io/name"]]; ok {
+			controllers = append(controllers, IngressController{Kind: kind})
+		}
+	}
+	return controllers, nil
+}
+
+// chooseIngressController picks the controller an ingress is written for:
+// the class set in kubernetes.ingress.class, the cluster default, or the
+// only one installed. ok is false when there is nothing to pick from.
+func chooseIngressController(controllers []IngressController) (chosen IngressController, note string, ok bool) {
+	if class := viper.GetString("kubernetes.ingress.class"); class != "" {
+		for _, c := range controllers {
+			if c.ClassName == class {
+				return c, "", true
+			}
+		}
+		return IngressController{Kind: ControllerOther, ClassName: class},
+			fmt.Sprintf("Ingress class %s from kubernetes.ingress.class was not found in the cluster", class), true
+	}
+	if len(controllers) == 0 {
+		return IngressController{}, "", false
+	}
+	for _, c := range controllers {
+		if c.Default {
+			return c, "", true
+		}
+	}
+	if len(controllers) > 1 {
+		var names []string
+		for _, c := range controllers {
+			names = append(names, c.ClassName)
+		}
+		note = fmt.Sprintf("Several ingress classes are installed (%s) and none is the default; using %s. Set kubernetes.ingress.class to choose.",
+			strings.Join(names, ", "), controllers[0].ClassName)
+	}
+	return controllers[0], note, true
+}
+
+// controllerAnnotations returns the annotations a controller needs to serve
+// an ingress, and to redirect HTTP to HTTPS when tls is set
+func controllerAnnotations(kind string, tls bool) map[string]string {
+	annotations := map[string]string{}
+	switch kind {
+	case ControllerNginx:
+		if tls {
+			annotations["nginx.ingress.kubernetes.io/ssl-redirect"] = "true"
+			annotations["nginx.ingress.kubernetes.io/force-ssl-redirect"] = "true"
+		}
+	case ControllerTraefik:
+		annotations["traefik.ingress.kubernetes.io/router.entrypoints"] = "web"
+		if tls {
+			annotations["traefik.ingress.kubernetes.io/router.entrypoints"] = "websecure"
+			annotations["traefik.ingress.kubernetes.io/router.tls"] = "true"
+		}
+	case ControllerALB:
+		annotations["alb.ingress.kubernetes.io/scheme"] = "internet-facing"
+		annotations["alb.ingress.kubernetes.io/target-type"] = "ip"
+		if tls {
+			annotations["alb.ingress.kubernetes.io/listen-ports"] = `[{"HTTP": 80}, {"HTTPS": 443}]`
+			annotations["alb.ingress.kubernetes.io/ssl-redirect"] = "443"
+			if arn := viper.GetString("kubernetes.ingress.alb_certificate_arn"); arn != "" {
+				annotations["alb.ingress.kubernetes.io/certificate-arn"] = arn
+			}
+		}
+	}
+	return annotations
+}
+
+// ExposeRequest is a parsed "expose service X at https://host/path" question
+type ExposeRequest struct {
+	Service   string
+	Namespace string
+	Host      string
+	Path      string
+	Port      int
+	TLS       bool
+}
+
+var exposeURLPattern = regexp.MustCompile(`\bexpose\s+(?:the\s+)?(?:service\s+|svc\s+)?([a-z0-9][a-z0-9-]*[a-z0-9])(?:\s+on\s+port\s+(\d{1,5}))?\s+(?:at|on|as|via)\s+(https?)://([a-z0-9][a-z0-9.-]*[a-z0-9])(/[^\s]*)?`)
+
+// ParseExposeQuery reads questions such as "expose service api at
+// https://api.example.com". ok is false for anything else.
+func ParseExposeQuery(query string) (ExposeRequest, bool) {
+	lower := strings.ToLower(strings.TrimRight(strings.TrimSpace(query), "?.!"))
+	m := exposeURLPattern.FindStringSubmatch(lower)
+	if m == nil {
+		return ExposeRequest{}, false
+	}
+	req := ExposeRequest{Service: m[1], Host: m[4], Path: m[5], TLS: m[3] == "https"}
+	req.Port, _ = strconv.Atoi(m[2])
+	if req.Path == "" {
+		req.Path = "/"
+	}
+	if ns := reachNamespacePattern.FindStringSubmatch(lower); ns != nil {
+		req.Namespace = ns[1] + ns[2]
+	}
+	return req, true
+}
+
+// ExposePlan builds a plan exposing a service at a host through the
+// cluster's ingress controller, with an ingressClassName and annotations for
+// that controller. For HTTPS it adds a cert-manager ClusterIssuer, when the
+// cluster has none, and a Certificate; ALB terminates TLS with ACM instead.
+func (s *SubAgent) ExposePlan(ctx context.Context, req ExposeRequest, namespace string) (*NetworkingPlan, error) {
+	if req.Namespace != "" {
+		namespace = req.Namespace
+	}
+	svc, err := s.getService(ctx, req.Service, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found in namespace %s: %w", req.Service, namespace, err)
+	}
+	port := req.Port
+	if port == 0 && len(svc.Spec.Ports) > 0 {
+		port = svc.Spec.Ports[0].Port
+	}
+	if port == 0 {
+		return nil, fmt.Errorf("service %s exposes no ports", req.Service)
+	}
+
+	var notes []string
+	controllers, err := s.ingress.DetectIngressControllers(ctx)
+	if err != nil {
+		notes = append(notes, fmt.Sprintf("Could not detect ingress controllers: %v", err))
+	}
+	controller, note, found := chooseIngressController(controllers)
+	if note != "" {
+		notes = append(notes, note)
+	}
+	if !found {
+		controller = IngressController{Kind: ControllerOther}
+		notes = append(notes, "No ingress controller was detected; install one (for example ingress-nginx) before applying, or the ingress will never get an address")
+	}
+
+	name := req.Service
+	opts := CreateIngressOptions{
+		Name:             name,
+		Namespace:        namespace,
+		IngressClassName: controller.ClassName,
+		Annotations:      controllerAnnotations(controller.Kind, req.TLS),
+		Rules: []IngressRuleSpec{{
+			Host:  req.Host,
+			Paths: []IngressPathSpec{{Path: req.Path, ServiceName: req.Service, ServicePort: port}},
+		}},
+	}
+
+	var steps []NetworkingStep
+	if req.TLS {
+		tls := IngressTLSSpec{Hosts: []string{req.Host}}
+		if controller.Kind == ControllerALB {
+			if opts.Annotations["alb.ingress.kubernetes.io/certificate-arn"] == "" {
+				notes = append(notes, fmt.Sprintf("The ALB controller will look up an ACM certificate covering %s; set kubernetes.ingress.alb_certificate_arn to pin one", req.Host))
+			}
+		} else {
+			tls.SecretName = name + "-tls"
+			certSteps, certNotes := s.certManagerSteps(ctx, name, namespace, req.Host, tls.SecretName, controller)
+			steps = append(steps, certSteps...)
+			notes = append(notes, certNotes...)
+		}
+		opts.TLS = []IngressTLSSpec{tls}
+	}
+
+	steps = append(steps, NetworkingStep{
+		ID:          "create-ingress",
+		Description: fmt.Sprintf("Create ingress %s for %s", name, req.Host),
+		Command:     "kubectl",
+		Args:        []string{"apply", "-f", "-"},
+		Manifest:    s.ingress.generateIngressManifest(opts),
+		Reason:      fmt.Sprintf("Route %s%s to service %s port %d", req.Host, req.Path, req.Service, port),
+	})
+	scheme := "http"
+	if req.TLS {
+		scheme = "https"
+	}
+	notes = append(notes, fmt.Sprintf("Point DNS for %s at the ingress address: kubectl get ingress %s -n %s", req.Host, name, namespace))
+
+	summary := fmt.Sprintf("Expose service %s at %s://%s%s", req.Service, scheme, req.Host, req.Path)
+	if controller.Kind != ControllerOther {
+		summary += " through " + controller.Kind
+	}
+	return &NetworkingPlan{
+		Version:   1,
+		CreatedAt: time.Now(),
+		Summary:   summary,
+		Steps:     steps,
+		Notes:     notes,
+	}, nil
+}
+
+// certManagerSteps returns the steps issuing a certificate for host into
+// secretName with cert-manager, creating an ACME ClusterIssuer solved through
+// the controller when the cluster has no issuer to use
+func (s *SubAgent) certManagerSteps(ctx context.Context, name, namespace, host, secretName string, controller IngressController) ([]NetworkingStep, []string) {
+	if _, err := s.client.Run(ctx, "get", "crd", "certificates.cert-manager.io"); err != nil {
+		return nil, []string{
+			"cert-manager is not installed, so no certificate is issued; install it (helm install cert-manager jetstack/cert-manager -n cert-manager --create-namespace --set crds.enabled=true) and ask again, or create the TLS secret " + secretName + " yourself",
+		}
+	}
+
+	var steps []NetworkingStep
+	var notes []string
+	issuer := viper.GetString("kubernetes.ingress.cluster_issuer")
+	if issuer == "" {
+		issuer = s.existingClusterIssuer(ctx)
+	}
+	if issuer == "" {
+		issuer = "letsencrypt-prod"
+		email := viper.GetString("kubernetes.ingress.acme_email")
+		if email == "" {
+			notes = append(notes, "No ACME email is set (kubernetes.ingress.acme_email); Let's Encrypt will not send expiry notices")
+		}
+		steps = append(steps, NetworkingStep{
+			ID:          "create-cluster-issuer",
+			Description: fmt.Sprintf("Create ClusterIssuer %s", issuer),
+			Command:     "kubectl",
+			Args:        []string{"apply", "-f", "-"},
+			Manifest:    clusterIssuerManifest(issuer, email, controller.ClassName),
+			Reason:      "Issue certificates from Let's Encrypt, answering HTTP-01 challenges through the ingress controller",
+		})
+	}
+	steps = append(steps, NetworkingStep{
+		ID:          "create-certificate",
+		Description: fmt.Sprintf("Request a certificate for %s", host),
+		Command:     "kubectl",
+		Args:        []string{"apply", "-f", "-"},
+		Manifest: fmt.Sprintf(`apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  secretName: %s
+  dnsNames:
+  - %s
+  issuerRef:
+    name: %s
+    kind: ClusterIssuer`, name, namespace, secretName, host, issuer),
+		Reason: fmt.Sprintf("Have cert-manager issue and renew the certificate in secret %s", secretName),
+	})
+	notes = append(notes, fmt.Sprintf("The certificate is issued once DNS for %s resolves to the ingress; watch it with kubectl wait --for=condition=Ready certificate/%s -n %s --timeout=5m", host, name, namespace))
+	return steps, notes
+}
+
+// existingClusterIssuer returns the first ready ClusterIssuer, by name
+func (s *SubAgent) existingClusterIssuer(ctx context.Context) string {
+	out, err := s.client.Run(ctx, "get", "clusterissuers", "-o", "json")
+	if err != nil {
+		return ""
+	}
+	var issuers struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if json.Unmarshal([]byte(out), &issuers) != nil {
+		return ""
+	}
+	var ready []string
+	for _, issuer := range issuers.Items {
+		for _, c := range issuer.Status.Conditions {
+			if c.Type == "Ready" && c.Status == "True" {
+				ready = append(ready, issuer.Metadata.Name)
+			}
+		}
+	}
+	if len(ready) == 0 {
+		return ""
+	}
+	sort.Strings(ready)
+	return ready[0]
+}
+
+func clusterIssuerManifest(name, email, ingressClass string) string {
+	manifest := fmt.Sprintf(`apiVersion: cert-manager.io/v1
+kind: ClusterIssuer
+metadata:
+  name: %s
+spec:
+  acme:
+    server: https://acme-v02.api.letsencrypt.org/directory`, name)
+	if email != "" {
+		manifest += fmt.Sprintf("\n    email: %s", email)
+	}
+	manifest += fmt.Sprintf(`
+    privateKeySecretRef:
+      name: %s-account-key
+    solvers:
+    - http01:
+        ingress:`, name)
+	if ingressClass != "" {
+		manifest += fmt.Sprintf("\n          ingressClassName: %s", ingressClass)
+	} else {
+		manifest += " {}"
+	}
+	return manifest
+}
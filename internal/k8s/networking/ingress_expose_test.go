@@ -0,0 +1,130 @@
+package networking
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	nginxClass   = `{"metadata": {"name": "nginx", "annotations": {"ingressclass.kubernetes.io/is-default-class": "true"}}, "spec": {"controller": "k8s.io/ingress-nginx"}}`
+	traefikClass = `{"metadata": {"name": "traefik"}, "spec": {"controller": "traefik.io/ingress-controller"}}`
+	albClass     = `{"metadata": {"name": "alb"}, "spec": {"controller": "ingress.k8s.aws/alb"}}`
+)
+
+func exposeCluster(classes ...string) *clusterClient {
+	client := reachCluster()
+	client.run[": get ingressclasses -o json"] = `{"items": [` + strings.Join(classes, ",") + `]}`
+	return client
+}
+
+func exposePlan(t *testing.T, client *clusterClient, query string) *NetworkingPlan {
+	t.Helper()
+	resp, err := NewSubAgent(client, false).HandleQuery(context.Background(), query, QueryOptions{Namespace: "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Type != ResponseTypePlan || resp.Plan == nil {
+		t.Fatalf("response = %+v, want a plan", resp)
+	}
+	return resp.Plan
+}
+
+func TestParseExposeQuery(t *testing.T) {
+	got, ok := ParseExposeQuery("Expose service api on port 8080 at https://API.example.com/v1 in the shop namespace")
+	want := ExposeRequest{Service: "api", Namespace: "shop", Host: "api.example.com", Path: "/v1", Port: 8080, TLS: true}
+	if !ok || got != want {
+		t.Errorf("ParseExposeQuery = %+v, %v; want %+v", got, ok, want)
+	}
+	if got, ok := ParseExposeQuery("expose web at http://web.local"); !ok || got.TLS || got.Path != "/" {
+		t.Errorf("ParseExposeQuery(http) = %+v, %v", got, ok)
+	}
+	if _, ok := ParseExposeQuery("expose deployment web as a nodeport service"); ok {
+		t.Error("expose without a URL matched")
+	}
+}
+
+func TestDetectIngressControllers(t *testing.T) {
+	client := exposeCluster(traefikClass, nginxClass)
+	controllers, err := NewIngressManager(client, false).DetectIngressControllers(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	chosen, note, ok := chooseIngressController(controllers)
+	if !ok || chosen.Kind != ControllerNginx || chosen.ClassName != "nginx" || note != "" {
+		t.Errorf("chose %+v (%q), want the default nginx class", chosen, note)
+	}
+
+	// Without IngressClasses the controller deployment identifies it
+	client = exposeCluster()
+	client.run[": get deployments -A -l app.kubernetes.io/name -o json"] = `{"items": [{"metadata": {"labels": {"app.kubernetes.io/name": "traefik"}}}]}`
+	controllers, _ = NewIngressManager(client, false).DetectIngressControllers(context.Background())
+	if len(controllers) != 1 || controllers[0].Kind != ControllerTraefik || controllers[0].ClassName != "" {
+		t.Errorf("controllers = %+v", controllers)
+	}
+}
+
+func TestExposePlanIssuesCertificate(t *testing.T) {
+	viper.Set("kubernetes.ingress.acme_email", "ops@example.com")
+	t.Cleanup(func() { viper.Set("kubernetes.ingress.acme_email", "") })
+	client := exposeCluster(nginxClass)
+	client.run[": get crd certificates.cert-manager.io"] = "customresourcedefinition.apiextensions.k8s.io/certificates.cert-manager.io"
+	client.run[": get clusterissuers -o json"] = `{"items": []}`
+
+	plan := exposePlan(t, client, "expose service api at https://api.example.com")
+	var ids []string
+	for _, step := range plan.Steps {
+		ids = append(ids, step.ID)
+	}
+	if strings.Join(ids, ",") != "create-cluster-issuer,create-certificate,create-ingress" {
+		t.Fatalf("steps = %v", ids)
+	}
+	issuer, cert, ingress := plan.Steps[0].Manifest, plan.Steps[1].Manifest, plan.Steps[2].Manifest
+	for _, want := range []string{"kind: ClusterIssuer", "email: ops@example.com", "ingressClassName: nginx"} {
+		if !strings.Contains(issuer, want) {
+			t.Errorf("issuer missing %q:\n%s", want, issuer)
+		}
+	}
+	for _, want := range []string{"secretName: api-tls", "- api.example.com", "name: letsencrypt-prod"} {
+		if !strings.Contains(cert, want) {
+			t.Errorf("certificate missing %q:\n%s", want, cert)
+		}
+	}
+	for _, want := range []string{"ingressClassName: nginx", `nginx.ingress.kubernetes.io/ssl-redirect: "true"`, "secretName: api-tls", "name: api\n            port:\n              number: 80"} {
+		if !strings.Contains(ingress, want) {
+			t.Errorf("ingress missing %q:\n%s", want, ingress)
+		}
+	}
+
+	// A ready ClusterIssuer is reused rather than created
+	client.run[": get clusterissuers -o json"] = `{"items": [{"metadata": {"name": "corp-ca"}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}}]}`
+	plan = exposePlan(t, client, "expose service api at https://api.example.com")
+	if len(plan.Steps) != 2 || !strings.Contains(plan.Steps[0].Manifest, "name: corp-ca") {
+		t.Errorf("steps = %+v", plan.Steps)
+	}
+}
+
+func TestExposePlanPerController(t *testing.T) {
+	// Traefik without cert-manager still gets its TLS router, with a note
+	plan := exposePlan(t, exposeCluster(traefikClass), "expose service api at https://api.example.com")
+	ingress := plan.Steps[len(plan.Steps)-1].Manifest
+	if len(plan.Steps) != 1 || !strings.Contains(ingress, `router.entrypoints: "websecure"`) {
+		t.Errorf("traefik plan:\n%s", ingress)
+	}
+	if !strings.Contains(strings.Join(plan.Notes, "\n"), "cert-manager is not installed") {
+		t.Errorf("notes = %v", plan.Notes)
+	}
+
+	// ALB takes its certificate from ACM, so no secret or cert-manager step
+	plan = exposePlan(t, exposeCluster(albClass), "expose service api at https://api.example.com")
+	ingress = plan.Steps[0].Manifest
+	if len(plan.Steps) != 1 || strings.Contains(ingress, "secretName") || !strings.Contains(ingress, "alb.ingress.kubernetes.io/ssl-redirect") {
+		t.Errorf("alb plan:\n%s", ingress)
+	}
+
+	if _, err := NewSubAgent(exposeCluster(nginxClass), false).HandleQuery(context.Background(), "expose service billing at https://b.example.com", QueryOptions{Namespace: "shop"}); err == nil {
+		t.Error("expected an error for a missing service")
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("k8s.networking")
+
 // SubAgent handles networking-related queries delegated from the main K8s agent
 type SubAgent struct {
 	client   K8sClient
@@ -39,12 +43,32 @@ type QueryAnalysis struct {
 
 // HandleQuery processes a networking-related query
 func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
+	if q, ok := ParseReachabilityQuery(query); ok {
+		namespace := opts.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		report, err := s.CheckReachability(ctx, q, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Type: ResponseTypeResult, Data: report}, nil
+	}
+	if req, ok := ParseExposeQuery(query); ok {
+		namespace := opts.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		plan, err := s.ExposePlan(ctx, req, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Type: ResponseTypePlan, Plan: plan, Message: plan.Summary}, nil
+	}
+
 	analysis := s.analyzeQuery(query)
 
-	if s.debug {
-		fmt.Printf("[networking] query analysis: type=%s op=%s name=%s ns=%s readonly=%v\n",
-			analysis.ResourceType, analysis.Operation, analysis.ResourceName, analysis.Namespace, analysis.IsReadOnly)
-	}
+	logger.Debugf("query analysis: type=%s op=%s name=%s ns=%s readonly=%v", analysis.ResourceType, analysis.Operation, analysis.ResourceName, analysis.Namespace, analysis.IsReadOnly)
 
 	// Use namespace from query analysis or options
 	namespace := analysis.Namespace
@@ -344,6 +368,12 @@ func (s *SubAgent) handleIngressModifyOp(ctx context.Context, query string, anal
 	switch analysis.Operation {
 	case "create":
 		ingressOpts := s.parseIngressCreationFromQuery(query, namespace)
+		if controllers, err := s.ingress.DetectIngressControllers(ctx); err == nil {
+			if controller, _, ok := chooseIngressController(controllers); ok {
+				ingressOpts.IngressClassName = controller.ClassName
+				ingressOpts.Annotations = controllerAnnotations(controller.Kind, false)
+			}
+		}
 		plan := s.ingress.CreateIngressPlan(ingressOpts)
 		return &Response{
 			Type: ResponseTypePlan,
@@ -494,9 +494,7 @@ func (m *NetworkPolicyManager) parseNetworkPolicyList(data []byte) ([]NetworkPol
 	for _, item := range list.Items {
 		policy, err := m.parseNetworkPolicy(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[networkpolicy] failed to parse policy: %v\n", err)
-			}
+			logger.Debugf("failed to parse policy: %v", err)
 			continue
 		}
 		policies = append(policies, *policy)
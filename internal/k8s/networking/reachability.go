@@ -0,0 +1,737 @@
+package networking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/dryrun"
+	"github.com/spf13/viper"
+)
+
+// ReachabilityQuery is a parsed "can pod A reach service B" question
+type ReachabilityQuery struct {
+	Source          string // pod, or the workload whose pods are tried
+	SourceNamespace string
+	Target          string // service name, or an external host
+	TargetNamespace string
+	External        bool // Target is a host outside the cluster
+	Port            int
+}
+
+var (
+	reachPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`\bcan\s+(?:the\s+)?(?:pods?\s+)?([a-z0-9][a-z0-9./-]*?)\s+(?:reach|talk\s+to|connect\s+to|access)\s+(?:the\s+)?(?:service\s+|svc\s+)?([a-z0-9][a-z0-9.:/-]*[a-z0-9])`),
+		regexp.MustCompile(`\bis\s+(?:the\s+)?(?:service\s+|svc\s+)?([a-z0-9][a-z0-9.:/-]*[a-z0-9])\s+reachable\s+from\s+(?:the\s+)?(?:pods?\s+)?([a-z0-9][a-z0-9./-]*[a-z0-9])`),
+	}
+	reachPortPattern      = regexp.MustCompile(`\bport\s+(\d{1,5})\b`)
+	reachNamespacePattern = regexp.MustCompile(`\b(?:in\s+(?:the\s+)?([a-z0-9][a-z0-9-]*)\s+namespace|(?:namespace|-n)\s+([a-z0-9][a-z0-9-]*))`)
+)
+
+// notReachSources are words that make "can X access Y" a permissions
+// question rather than a network one
+var notReachSources = map[string]bool{
+	"i": true, "we": true, "you": true, "it": true, "user": true, "group": true,
+	"serviceaccount": true, "sa": true, "someone": true, "anyone": true,
+}
+
+// notReachTargets are resource kinds "can pod X access" is asked about
+var notReachTargets = map[string]bool{
+	"secrets": true, "secret": true, "configmaps": true, "configmap": true, "pods": true,
+	"nodes": true, "namespaces": true, "it": true,
+}
+
+// externalTLDs tell an external host from a name.namespace service reference
+var externalTLDs = map[string]bool{
+	"com": true, "net": true, "org": true, "io": true, "dev": true, "app": true, "cloud": true, "ai": true,
+}
+
+// ParseReachabilityQuery reads questions such as "can pod web reach service
+// api on port 8080" or "is api.payments reachable from frontend". ok is
+// false for anything else.
+func ParseReachabilityQuery(query string) (ReachabilityQuery, bool) {
+	lower := strings.ToLower(strings.TrimRight(strings.TrimSpace(query), "?.!"))
+	var source, target string
+	for i, pattern := range reachPatterns {
+		m := pattern.FindStringSubmatch(lower)
+		if m == nil {
+			continue
+		}
+		source, target = m[1], m[2]
+		if i == 1 {
+			source, target = m[2], m[1]
+		}
+		break
+	}
+	if source == "" || notReachSources[source] || notReachTargets[target] {
+		return ReachabilityQuery{}, false
+	}
+
+	q := ReachabilityQuery{Source: source, Target: target}
+	if m := reachNamespacePattern.FindStringSubmatch(lower); m != nil {
+		q.SourceNamespace = m[1] + m[2]
+		q.TargetNamespace = q.SourceNamespace
+	}
+	if ns, name, ok := strings.Cut(q.Source, "/"); ok {
+		q.SourceNamespace, q.Source = ns, name
+	}
+	if host, port, ok := strings.Cut(q.Target, ":"); ok {
+		q.Target = host
+		q.Port, _ = strconv.Atoi(port)
+	}
+	if m := reachPortPattern.FindStringSubmatch(lower); m != nil && q.Port == 0 {
+		q.Port, _ = strconv.Atoi(m[1])
+	}
+	switch {
+	case strings.Contains(q.Target, "/"):
+		q.TargetNamespace, q.Target, _ = strings.Cut(q.Target, "/")
+	case strings.Contains(q.Target, ".svc"):
+		parts := strings.Split(q.Target, ".")
+		q.Target, q.TargetNamespace = parts[0], parts[1]
+	case strings.Count(q.Target, ".") == 1 && !externalTLDs[q.Target[strings.Index(q.Target, ".")+1:]]:
+		q.Target, q.TargetNamespace, _ = strings.Cut(q.Target, ".")
+	case strings.Contains(q.Target, "."):
+		q.External = true
+	}
+	return q, true
+}
+
+// ReachabilityVerdict is the answer to a reachability question
+type ReachabilityVerdict string
+
+const (
+	VerdictReachable   ReachabilityVerdict = "reachable"
+	VerdictBlocked     ReachabilityVerdict = "blocked"
+	VerdictNoEndpoints ReachabilityVerdict = "no-endpoints"
+	VerdictNoService   ReachabilityVerdict = "no-service"
+	VerdictUnreachable ReachabilityVerdict = "unreachable"
+)
+
+// ProbeCheck is one live check run from the source pod
+type ProbeCheck struct {
+	Name   string `json:"name"` // dns, tcp or http
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+// ReachabilityReport explains whether a pod can reach a service
+type ReachabilityReport struct {
+	SourcePod        string              `json:"sourcePod"`
+	SourceNamespace  string              `json:"sourceNamespace"`
+	Target           string              `json:"target"`
+	TargetNamespace  string              `json:"targetNamespace,omitempty"`
+	ClusterIP        string              `json:"clusterIP,omitempty"`
+	Port             int                 `json:"port,omitempty"`
+	TargetPort       string              `json:"targetPort,omitempty"`
+	Protocol         string              `json:"protocol,omitempty"`
+	ReadyEndpoints   int                 `json:"readyEndpoints"`
+	Verdict          ReachabilityVerdict `json:"verdict"`
+	Reason           string              `json:"reason"`
+	BlockingPolicies []string            `json:"blockingPolicies,omitempty"`
+	Egress           string              `json:"egress,omitempty"`
+	Ingress          string              `json:"ingress,omitempty"`
+	Checks           []ProbeCheck        `json:"checks,omitempty"`
+	Notes            []string            `json:"notes,omitempty"`
+}
+
+// podView is the part of a Pod reachability analysis reads
+type podView struct {
+	Metadata struct {
+		Name      string            `json:"name"`
+		Namespace string            `json:"namespace"`
+		UID       string            `json:"uid"`
+		Labels    map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec struct {
+		NodeName   string `json:"nodeName"`
+		Containers []struct {
+			Name  string `json:"name"`
+			Ports []struct {
+				Name          string `json:"name"`
+				ContainerPort int    `json:"containerPort"`
+			} `json:"ports"`
+		} `json:"containers"`
+	} `json:"spec"`
+	Status struct {
+		Phase string `json:"phase"`
+		PodIP string `json:"podIP"`
+	} `json:"status"`
+}
+
+func (p podView) endpoint(namespaceLabels map[string]string) endpoint {
+	e := endpoint{
+		Name:            p.Metadata.Name,
+		Namespace:       p.Metadata.Namespace,
+		NamespaceLabels: namespaceLabels,
+		Labels:          p.Metadata.Labels,
+		IP:              p.Status.PodIP,
+		NamedPorts:      map[string]int{},
+	}
+	for _, c := range p.Spec.Containers {
+		for _, port := range c.Ports {
+			if port.Name != "" {
+				e.NamedPorts[port.Name] = port.ContainerPort
+			}
+		}
+	}
+	return e
+}
+
+// serviceView is the part of a Service reachability analysis reads
+type serviceView struct {
+	Spec struct {
+		Type         string            `json:"type"`
+		ClusterIP    string            `json:"clusterIP"`
+		ExternalName string            `json:"externalName"`
+		Selector     map[string]string `json:"selector"`
+		Ports        []struct {
+			Port       int    `json:"port"`
+			TargetPort any    `json:"targetPort"`
+			Protocol   string `json:"protocol"`
+		} `json:"ports"`
+	} `json:"spec"`
+}
+
+// endpointsView is the part of an Endpoints object reachability reads
+type endpointsView struct {
+	Subsets []struct {
+		Addresses []struct {
+			IP        string `json:"ip"`
+			TargetRef *struct {
+				Name string `json:"name"`
+			} `json:"targetRef"`
+		} `json:"addresses"`
+		NotReadyAddresses []json.RawMessage `json:"notReadyAddresses"`
+	} `json:"subsets"`
+}
+
+// probePollInterval is how often the probe pod is checked for completion;
+// replaced in tests
+var probePollInterval = 2 * time.Second
+
+// probeTimeout bounds how long the live probe may take
+const probeTimeout = 90 * time.Second
+
+// defaultProbeImage is the debug image the live checks run in
+const defaultProbeImage = "nicolaka/netshoot"
+
+// CheckReachability works out whether the query's source pod can reach its
+// target: the Service and its ready endpoints, the NetworkPolicies on both
+// ends and DNS, then a live DNS, TCP and HTTP check from a short-lived
+// netshoot pod standing in for the source pod unless
+// kubernetes.netcheck.probe is false
+func (s *SubAgent) CheckReachability(ctx context.Context, q ReachabilityQuery, namespace string) (*ReachabilityReport, error) {
+	if q.SourceNamespace == "" {
+		q.SourceNamespace = namespace
+	}
+	if q.TargetNamespace == "" {
+		q.TargetNamespace = q.SourceNamespace
+	}
+	report := &ReachabilityReport{SourceNamespace: q.SourceNamespace, Target: q.Target, Port: q.Port}
+	if !q.External {
+		report.TargetNamespace = q.TargetNamespace
+	}
+
+	srcPods, err := s.listPods(ctx, q.SourceNamespace, "")
+	if err != nil {
+		return nil, err
+	}
+	src, ok := findPod(srcPods, q.Source)
+	if !ok {
+		return nil, fmt.Errorf("no pod %s (or pod of workload %s) in namespace %s", q.Source, q.Source, q.SourceNamespace)
+	}
+	report.SourcePod = src.Metadata.Name
+	srcEndpoint := src.endpoint(s.namespaceLabels(ctx, q.SourceNamespace))
+	srcPolicies, policyErr := s.listPolicies(ctx, q.SourceNamespace)
+	if policyErr != nil {
+		report.Notes = append(report.Notes, fmt.Sprintf("Could not read network policies in %s: %v", q.SourceNamespace, policyErr))
+	}
+
+	if q.External {
+		s.checkExternal(report, srcPolicies, srcEndpoint)
+		s.probe(ctx, report, src, q.Target, q.Target, q.Port)
+		return report, nil
+	}
+
+	host := fmt.Sprintf("%s.%s.svc.cluster.local", q.Target, q.TargetNamespace)
+	svc, err := s.getService(ctx, q.Target, q.TargetNamespace)
+	if err != nil {
+		report.Verdict = VerdictNoService
+		report.Reason = fmt.Sprintf("service %s does not exist in namespace %s", q.Target, q.TargetNamespace)
+		return report, nil
+	}
+	report.ClusterIP = svc.Spec.ClusterIP
+	if svc.Spec.Type == string(ServiceTypeExternalName) {
+		report.Notes = append(report.Notes, fmt.Sprintf("Service %s is an ExternalName alias for %s; NetworkPolicies cannot be checked for it", q.Target, svc.Spec.ExternalName))
+		report.Verdict, report.Reason = VerdictReachable, "no NetworkPolicy check applies to an ExternalName service"
+		s.probe(ctx, report, src, host, svc.Spec.ExternalName, q.Port)
+		return report, nil
+	}
+
+	// Pick the service port the question names, or its first
+	portIndex := -1
+	var exposed []string
+	for i, p := range svc.Spec.Ports {
+		exposed = append(exposed, strconv.Itoa(p.Port))
+		if (q.Port == 0 && portIndex < 0) || p.Port == q.Port {
+			portIndex = i
+		}
+	}
+	if portIndex < 0 {
+		report.Verdict = VerdictUnreachable
+		report.Reason = fmt.Sprintf("service %s does not expose port %d (it exposes %s)", q.Target, q.Port, strings.Join(exposed, ", "))
+		return report, nil
+	}
+	svcPort := svc.Spec.Ports[portIndex]
+	report.Port = svcPort.Port
+	report.Protocol = svcPort.Protocol
+	if report.Protocol == "" {
+		report.Protocol = "TCP"
+	}
+	report.TargetPort = portString(svcPort.TargetPort, svcPort.Port)
+
+	dstPods, err := s.listPods(ctx, q.TargetNamespace, "")
+	if err != nil {
+		return nil, err
+	}
+	ready := s.readyEndpointPods(ctx, q.Target, q.TargetNamespace, dstPods)
+	report.ReadyEndpoints = len(ready)
+	if len(ready) == 0 {
+		report.Verdict = VerdictNoEndpoints
+		report.Reason = missingEndpointsReason(q.Target, svc.Spec.Selector, dstPods)
+		return report, nil
+	}
+
+	dst := ready[0]
+	dstEndpoint := dst.endpoint(s.namespaceLabels(ctx, q.TargetNamespace))
+	dstPort, err := strconv.Atoi(report.TargetPort)
+	if err != nil {
+		dstPort = dstEndpoint.NamedPorts[report.TargetPort]
+	}
+	dstPolicies := srcPolicies
+	if q.TargetNamespace != q.SourceNamespace {
+		dstPolicies, err = s.listPolicies(ctx, q.TargetNamespace)
+		if err != nil {
+			report.Notes = append(report.Notes, fmt.Sprintf("Could not read network policies in %s: %v", q.TargetNamespace, err))
+		}
+	}
+
+	egress := checkPolicies(srcPolicies, srcEndpoint, dstEndpoint, false, dstPort, report.Protocol)
+	ingress := checkPolicies(dstPolicies, dstEndpoint, srcEndpoint, true, dstPort, report.Protocol)
+	report.Egress = describePolicyVerdict(egress, src.Metadata.Name)
+	report.Ingress = describePolicyVerdict(ingress, dst.Metadata.Name)
+	portDesc := fmt.Sprintf("%d/%s", dstPort, report.Protocol)
+	switch {
+	case !egress.Allowed:
+		report.Verdict = VerdictBlocked
+		report.BlockingPolicies = egress.Blocking
+		report.Reason = fmt.Sprintf("egress from %s to %s on %s is blocked by NetworkPolicy %s", src.Metadata.Name, dst.Metadata.Name, portDesc, strings.Join(egress.Blocking, ", "))
+	case !ingress.Allowed:
+		report.Verdict = VerdictBlocked
+		report.BlockingPolicies = ingress.Blocking
+		report.Reason = fmt.Sprintf("ingress to %s on %s from %s is blocked by NetworkPolicy %s", dst.Metadata.Name, portDesc, src.Metadata.Name, strings.Join(ingress.Blocking, ", "))
+	default:
+		report.Verdict = VerdictReachable
+		report.Reason = fmt.Sprintf("service %s has %d ready endpoint(s) and no NetworkPolicy blocks %s", q.Target, len(ready), portDesc)
+	}
+	if egress.Isolated {
+		s.checkDNSEgress(ctx, report, srcPolicies, srcEndpoint)
+	}
+
+	addr := report.ClusterIP
+	if addr == "" || addr == "None" {
+		addr = dst.Status.PodIP
+	}
+	s.probe(ctx, report, src, host, addr, report.Port)
+	return report, nil
+}
+
+// checkExternal reports egress isolation for a host outside the cluster,
+// whose address policies can only match through ipBlock rules
+func (s *SubAgent) checkExternal(report *ReachabilityReport, policies []networkPolicy, src endpoint) {
+	var isolating []string
+	for _, p := range policies {
+		if p.appliesTo(src, "Egress") {
+			isolating = append(isolating, p.String())
+		}
+	}
+	report.Verdict = VerdictReachable
+	report.Reason = fmt.Sprintf("no NetworkPolicy restricts egress from %s", src.Name)
+	if len(isolating) > 0 {
+		report.Verdict = VerdictUnreachable
+		report.Reason = fmt.Sprintf("egress from %s is restricted by NetworkPolicy %s; only ipBlock rules can allow %s", src.Name, strings.Join(isolating, ", "), report.Target)
+		report.BlockingPolicies = isolating
+	}
+}
+
+// checkDNSEgress notes when egress policies on the source pod leave out
+// cluster DNS, which breaks every lookup by name even where the service
+// itself is allowed
+func (s *SubAgent) checkDNSEgress(ctx context.Context, report *ReachabilityReport, policies []networkPolicy, src endpoint) {
+	pods, err := s.listPods(ctx, "kube-system", "k8s-app=kube-dns")
+	if err != nil || len(pods) == 0 {
+		return
+	}
+	dns := pods[0].endpoint(s.namespaceLabels(ctx, "kube-system"))
+	if checkPolicies(policies, src, dns, false, 53, "UDP").Allowed {
+		return
+	}
+	report.Notes = append(report.Notes, fmt.Sprintf("Egress policies on %s do not allow DNS (UDP 53 to kube-system/kube-dns), so the service name will not resolve", src.Name))
+	if report.Verdict == VerdictReachable {
+		report.Verdict = VerdictBlocked
+		report.Reason = "DNS lookups from " + src.Name + " are blocked by egress NetworkPolicy; the service is only reachable by IP"
+	}
+}
+
+// probe runs DNS, TCP and HTTP checks from a netshoot pod created next to
+// the source pod, on its node with its labels so the same policies apply
+// to it, and folds the results into the verdict. The probe pod is deleted
+// afterwards; a dry run prints it instead.
+func (s *SubAgent) probe(ctx context.Context, report *ReachabilityReport, src podView, host, addr string, port int) {
+	if viper.IsSet("kubernetes.netcheck.probe") && !viper.GetBool("kubernetes.netcheck.probe") {
+		return
+	}
+	if src.Status.Phase != "Running" {
+		report.Notes = append(report.Notes, fmt.Sprintf("Live checks skipped: pod %s is %s", src.Metadata.Name, src.Status.Phase))
+		return
+	}
+	image := viper.GetString("kubernetes.netcheck.image")
+	if image == "" {
+		image = defaultProbeImage
+	}
+
+	var script []string
+	script = append(script, fmt.Sprintf(`if nslookup %s >/dev/null 2>&1; then echo dns=ok; else echo dns=fail; fi`, host))
+	if port > 0 && (report.Protocol == "" || report.Protocol == "TCP") {
+		script = append(script,
+			fmt.Sprintf(`if nc -z -w 3 %s %d; then echo tcp=ok; else echo tcp=fail; fi`, addr, port),
+			fmt.Sprintf(`echo "http=$(curl -s -o /dev/null -m 5 -w '%%{http_code}' http://%s:%d/)"`, addr, port),
+		)
+	}
+	name := "clanker-netcheck-" + strconv.FormatInt(time.Now().UnixNano()%1e6, 36)
+	ns := src.Metadata.Namespace
+	manifest, err := probePodManifest(src, name, image, strings.Join(script, "; "))
+	if err != nil {
+		report.Notes = append(report.Notes, fmt.Sprintf("Live checks skipped: %v", err))
+		return
+	}
+	if dryrun.Enabled() {
+		dryrun.PrintInput(os.Stdout, "kubectl", []string{"apply", "-n", ns, "-f", "-"}, "probe pod "+name)
+		report.Notes = append(report.Notes, "Live checks skipped: dry run")
+		return
+	}
+	if _, err := s.client.Apply(ctx, manifest); err != nil {
+		report.Notes = append(report.Notes, fmt.Sprintf("Live checks skipped: could not create probe pod %s: %v", name, err))
+		return
+	}
+	defer func() {
+		cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), probeCleanupTimeout)
+		defer cancel()
+		if _, err := s.client.Delete(cleanupCtx, "pod", name, ns); err != nil {
+			report.Notes = append(report.Notes, fmt.Sprintf("Could not delete probe pod %s; remove it with kubectl delete pod %s -n %s", name, name, ns))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	for {
+		out, err := s.client.RunWithNamespace(ctx, ns, "get", "pod", name, "-o", "jsonpath={.status.phase}")
+		if phase := strings.TrimSpace(out); err == nil && (phase == "Succeeded" || phase == "Failed") {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			report.Notes = append(report.Notes, fmt.Sprintf("Live checks timed out waiting for probe pod %s", name))
+			return
+		case <-time.After(probePollInterval):
+		}
+	}
+	out, err := s.client.RunWithNamespace(ctx, ns, "logs", name)
+	if err != nil {
+		report.Notes = append(report.Notes, fmt.Sprintf("Could not read the live check output: %v", err))
+		return
+	}
+	applyProbeOutput(report, out, host, addr, port)
+}
+
+// probeCleanupTimeout bounds deleting the probe pod, which runs even when
+// the probe itself timed out
+const probeCleanupTimeout = 30 * time.Second
+
+// probeReadinessGate is a condition nothing sets, so the probe pod never
+// turns ready and never joins the endpoints of a Service selecting the
+// labels it copies from the source pod
+const probeReadinessGate = "clanker.dev/netcheck-probe"
+
+// probePodManifest describes the probe pod for src. It is owned by src, so
+// no ReplicaSet adopts it for matching its labels and it goes when src does.
+func probePodManifest(src podView, name, image, script string) (string, error) {
+	if src.Metadata.UID == "" {
+		return "", fmt.Errorf("pod %s has no uid to own the probe pod", src.Metadata.Name)
+	}
+	pod := map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": src.Metadata.Namespace,
+			"labels":    src.Metadata.Labels,
+			"annotations": map[string]string{
+				"clanker.dev/netcheck-source": src.Metadata.Name,
+			},
+			"ownerReferences": []map[string]any{{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"name":       src.Metadata.Name,
+				"uid":        src.Metadata.UID,
+				"controller": true,
+			}},
+		},
+		"spec": map[string]any{
+			"nodeName":                      src.Spec.NodeName,
+			"restartPolicy":                 "Never",
+			"automountServiceAccountToken":  false,
+			"terminationGracePeriodSeconds": 0,
+			"readinessGates":                []map[string]string{{"conditionType": probeReadinessGate}},
+			"containers": []map[string]any{{
+				"name":    "netcheck",
+				"image":   image,
+				"command": []string{"sh", "-c", script},
+			}},
+		},
+	}
+	out, err := json.Marshal(pod)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// applyProbeOutput records the probe's key=value lines and lets a live
+// result override what the policy analysis predicted
+func applyProbeOutput(report *ReachabilityReport, out, host, addr string, port int) {
+	target := fmt.Sprintf("%s:%d", addr, port)
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "dns":
+			report.Checks = append(report.Checks, ProbeCheck{Name: "dns", OK: value == "ok", Detail: host})
+		case "tcp":
+			report.Checks = append(report.Checks, ProbeCheck{Name: "tcp", OK: value == "ok", Detail: target})
+		case "http":
+			code := strings.TrimSpace(value)
+			report.Checks = append(report.Checks, ProbeCheck{Name: "http", OK: code != "" && code != "000", Detail: "status " + code})
+		}
+	}
+
+	for _, c := range report.Checks {
+		switch {
+		case c.Name == "dns" && !c.OK:
+			report.Notes = append(report.Notes, fmt.Sprintf("%s did not resolve from the source pod", host))
+		case c.Name == "tcp" && c.OK && report.Verdict != VerdictReachable:
+			report.Notes = append(report.Notes, fmt.Sprintf("The live TCP check to %s succeeded although the analysis says %s; the cluster's CNI may not enforce NetworkPolicies", target, report.Verdict))
+			report.Verdict, report.Reason = VerdictReachable, fmt.Sprintf("a live TCP connection to %s succeeded", target)
+		case c.Name == "tcp" && !c.OK && report.Verdict == VerdictReachable:
+			report.Verdict = VerdictUnreachable
+			report.Reason = fmt.Sprintf("NetworkPolicies allow the path, but a TCP connection to %s failed; check that the pods listen on port %s", target, report.TargetPort)
+		}
+	}
+}
+
+func (s *SubAgent) listPods(ctx context.Context, namespace, selector string) ([]podView, error) {
+	args := []string{"get", "pods", "-o", "json"}
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+	out, err := s.client.RunWithNamespace(ctx, namespace, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods in %s: %w", namespace, err)
+	}
+	var list struct {
+		Items []podView `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse pods: %w", err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Metadata.Name < list.Items[j].Metadata.Name })
+	return list.Items, nil
+}
+
+func (s *SubAgent) listPolicies(ctx context.Context, namespace string) ([]networkPolicy, error) {
+	out, err := s.client.RunWithNamespace(ctx, namespace, "get", "networkpolicies", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parsePolicies([]byte(out))
+}
+
+// namespaceLabels returns a namespace's labels; namespaceSelectors can
+// always match kubernetes.io/metadata.name, even when it cannot be read
+func (s *SubAgent) namespaceLabels(ctx context.Context, namespace string) map[string]string {
+	labels := map[string]string{}
+	if data, err := s.client.GetJSON(ctx, "namespace", namespace, ""); err == nil {
+		var ns struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		}
+		if json.Unmarshal(data, &ns) == nil {
+			for k, v := range ns.Metadata.Labels {
+				labels[k] = v
+			}
+		}
+	}
+	labels["kubernetes.io/metadata.name"] = namespace
+	return labels
+}
+
+func (s *SubAgent) getService(ctx context.Context, name, namespace string) (*serviceView, error) {
+	data, err := s.client.GetJSON(ctx, "service", name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var svc serviceView
+	if err := json.Unmarshal(data, &svc); err != nil {
+		return nil, fmt.Errorf("failed to parse service %s: %w", name, err)
+	}
+	return &svc, nil
+}
+
+// readyEndpointPods returns the pods behind a service's ready endpoints
+func (s *SubAgent) readyEndpointPods(ctx context.Context, service, namespace string, pods []podView) []podView {
+	data, err := s.client.GetJSON(ctx, "endpoints", service, namespace)
+	if err != nil {
+		return nil
+	}
+	var ep endpointsView
+	if json.Unmarshal(data, &ep) != nil {
+		return nil
+	}
+	var ready []podView
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			for _, p := range pods {
+				if (addr.TargetRef != nil && addr.TargetRef.Name == p.Metadata.Name) || (addr.IP != "" && addr.IP == p.Status.PodIP) {
+					ready = append(ready, p)
+					break
+				}
+			}
+		}
+	}
+	return ready
+}
+
+// findPod returns the pod named name, or else the first running pod of a
+// workload called name
+func findPod(pods []podView, name string) (podView, bool) {
+	var fallback *podView
+	for i, p := range pods {
+		if p.Metadata.Name == name {
+			return p, true
+		}
+		if strings.HasPrefix(p.Metadata.Name, name+"-") && (fallback == nil || (fallback.Status.Phase != "Running" && p.Status.Phase == "Running")) {
+			fallback = &pods[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return podView{}, false
+}
+
+// missingEndpointsReason explains why a service has no ready endpoints
+func missingEndpointsReason(service string, selector map[string]string, pods []podView) string {
+	if len(selector) == 0 {
+		return fmt.Sprintf("service %s has no selector and no ready endpoints were added to it", service)
+	}
+	sel := &labelSelector{MatchLabels: selector}
+	var matching, running int
+	for _, p := range pods {
+		if sel.matches(p.Metadata.Labels) {
+			matching++
+			if p.Status.Phase == "Running" {
+				running++
+			}
+		}
+	}
+	pairs := make([]string, 0, len(selector))
+	for k, v := range selector {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	switch {
+	case matching == 0:
+		return fmt.Sprintf("service %s selects %s, which matches no pods", service, strings.Join(pairs, ","))
+	case running == 0:
+		return fmt.Sprintf("service %s selects %d pod(s), none of them running", service, matching)
+	default:
+		return fmt.Sprintf("service %s selects %d pod(s) but none is ready; check their readiness probes", service, matching)
+	}
+}
+
+func describePolicyVerdict(v policyVerdict, pod string) string {
+	switch {
+	case !v.Isolated:
+		return "allowed (no NetworkPolicy isolates " + pod + ")"
+	case v.Allowed:
+		return "allowed by NetworkPolicy"
+	}
+	return "blocked by " + strings.Join(v.Blocking, ", ")
+}
+
+// portString renders a Service targetPort, which is a number or a port name
+func portString(targetPort any, port int) string {
+	switch v := targetPort.(type) {
+	case float64:
+		return strconv.Itoa(int(v))
+	case string:
+		return v
+	}
+	return strconv.Itoa(port)
+}
+
+// String renders the report for the terminal
+func (r *ReachabilityReport) String() string {
+	var sb strings.Builder
+	target := r.Target
+	if r.TargetNamespace != "" {
+		target = r.TargetNamespace + "/" + r.Target
+	}
+	if r.Port > 0 {
+		target += fmt.Sprintf(":%d", r.Port)
+	}
+	fmt.Fprintf(&sb, "Can %s/%s reach %s?\n", r.SourceNamespace, r.SourcePod, target)
+	fmt.Fprintf(&sb, "Verdict: %s - %s\n", strings.ToUpper(string(r.Verdict)), r.Reason)
+	if r.TargetPort != "" {
+		fmt.Fprintf(&sb, "Service: %s, port %d -> %s/%s, %d ready endpoint(s)\n", r.ClusterIP, r.Port, r.TargetPort, r.Protocol, r.ReadyEndpoints)
+	}
+	if r.Egress != "" {
+		fmt.Fprintf(&sb, "Egress:  %s\n", r.Egress)
+	}
+	if r.Ingress != "" {
+		fmt.Fprintf(&sb, "Ingress: %s\n", r.Ingress)
+	}
+	if len(r.Checks) > 0 {
+		fmt.Fprintf(&sb, "Live checks from %s:\n", r.SourcePod)
+		for _, c := range r.Checks {
+			status := "ok"
+			if !c.OK {
+				status = "FAIL"
+			}
+			fmt.Fprintf(&sb, "  %-5s %-4s %s\n", c.Name, status, c.Detail)
+		}
+	}
+	for _, note := range r.Notes {
+		fmt.Fprintf(&sb, "Note: %s\n", note)
+	}
+	return sb.String()
+}
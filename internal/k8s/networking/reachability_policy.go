@@ -0,0 +1,262 @@
+package networking
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// labelSelector is a Kubernetes label selector. A nil selector in a peer
+// means the field was absent; an empty one selects everything.
+type labelSelector struct {
+	MatchLabels      map[string]string `json:"matchLabels"`
+	MatchExpressions []struct {
+		Key      string   `json:"key"`
+		Operator string   `json:"operator"`
+		Values   []string `json:"values"`
+	} `json:"matchExpressions"`
+}
+
+// matches reports whether the selector selects an object with labels
+func (s *labelSelector) matches(labels map[string]string) bool {
+	if s == nil {
+		return false
+	}
+	for k, v := range s.MatchLabels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	for _, expr := range s.MatchExpressions {
+		value, has := labels[expr.Key]
+		switch expr.Operator {
+		case "In":
+			if !has || !containsString(expr.Values, value) {
+				return false
+			}
+		case "NotIn":
+			if has && containsString(expr.Values, value) {
+				return false
+			}
+		case "Exists":
+			if !has {
+				return false
+			}
+		case "DoesNotExist":
+			if has {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// policyPeer is one from/to entry of a NetworkPolicy rule
+type policyPeer struct {
+	PodSelector       *labelSelector `json:"podSelector"`
+	NamespaceSelector *labelSelector `json:"namespaceSelector"`
+	IPBlock           *IPBlock       `json:"ipBlock"`
+}
+
+// policyPort is one ports entry of a NetworkPolicy rule; Port is a number,
+// a named container port, or absent for every port
+type policyPort struct {
+	Protocol string `json:"protocol"`
+	Port     any    `json:"port"`
+	EndPort  int    `json:"endPort"`
+}
+
+type policyRule struct {
+	From  []policyPeer `json:"from"`
+	To    []policyPeer `json:"to"`
+	Ports []policyPort `json:"ports"`
+}
+
+// networkPolicy is the part of a NetworkPolicy path analysis reads. It is
+// parsed separately from NetworkPolicyInfo, which folds empty and absent
+// selectors together.
+type networkPolicy struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		PodSelector labelSelector `json:"podSelector"`
+		PolicyTypes []string      `json:"policyTypes"`
+		Ingress     []policyRule  `json:"ingress"`
+		Egress      []policyRule  `json:"egress"`
+	} `json:"spec"`
+}
+
+func (p networkPolicy) String() string {
+	return p.Metadata.Namespace + "/" + p.Metadata.Name
+}
+
+// appliesTo reports whether the policy isolates the endpoint in the given
+// direction ("Ingress" or "Egress")
+func (p networkPolicy) appliesTo(e endpoint, direction string) bool {
+	if p.Metadata.Namespace != e.Namespace || !p.Spec.PodSelector.matches(e.Labels) {
+		return false
+	}
+	types := p.Spec.PolicyTypes
+	if len(types) == 0 {
+		// The API server defaults policyTypes; older objects may lack them
+		types = []string{"Ingress"}
+		if len(p.Spec.Egress) > 0 {
+			types = append(types, "Egress")
+		}
+	}
+	return containsString(types, direction)
+}
+
+// endpoint is one side of a connection: a pod, its namespace's labels, and
+// the named ports its containers declare
+type endpoint struct {
+	Name            string
+	Namespace       string
+	NamespaceLabels map[string]string
+	Labels          map[string]string
+	IP              string
+	NamedPorts      map[string]int
+}
+
+// allows reports whether any rule lets traffic to or from peer on port.
+// Rules use From for ingress and To for egress.
+func allows(rules []policyRule, policyNamespace string, peer endpoint, ingress bool, port int, protocol string, portOwner endpoint) bool {
+	for _, rule := range rules {
+		peers := rule.To
+		if ingress {
+			peers = rule.From
+		}
+		if !portAllowed(rule.Ports, port, protocol, portOwner) {
+			continue
+		}
+		if len(peers) == 0 {
+			return true
+		}
+		for _, p := range peers {
+			if peerMatches(p, policyNamespace, peer) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func peerMatches(p policyPeer, policyNamespace string, e endpoint) bool {
+	if p.IPBlock != nil {
+		return ipInBlock(e.IP, p.IPBlock)
+	}
+	if p.NamespaceSelector != nil {
+		if !p.NamespaceSelector.matches(e.NamespaceLabels) {
+			return false
+		}
+	} else if e.Namespace != policyNamespace {
+		return false
+	}
+	return p.PodSelector == nil || p.PodSelector.matches(e.Labels)
+}
+
+func portAllowed(ports []policyPort, port int, protocol string, owner endpoint) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, p := range ports {
+		proto := p.Protocol
+		if proto == "" {
+			proto = "TCP"
+		}
+		if !strings.EqualFold(proto, protocol) {
+			continue
+		}
+		switch v := p.Port.(type) {
+		case nil:
+			return true
+		case float64:
+			first, last := int(v), int(v)
+			if p.EndPort > first {
+				last = p.EndPort
+			}
+			if port >= first && port <= last {
+				return true
+			}
+		case string:
+			if n, err := strconv.Atoi(v); err == nil && n == port {
+				return true
+			}
+			if owner.NamedPorts[v] == port && port != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ipInBlock(ip string, block *IPBlock) bool {
+	addr := net.ParseIP(ip)
+	_, cidr, err := net.ParseCIDR(block.CIDR)
+	if addr == nil || err != nil || !cidr.Contains(addr) {
+		return false
+	}
+	for _, except := range block.Except {
+		if _, ex, err := net.ParseCIDR(except); err == nil && ex.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// policyVerdict is the NetworkPolicy outcome for one direction of a path
+type policyVerdict struct {
+	Isolated bool     // some policy selects the pod for this direction
+	Allowed  bool     // traffic passes
+	Blocking []string // policies isolating the pod when nothing allows the traffic
+}
+
+// checkPolicies evaluates the policies isolating self in direction against
+// traffic with peer; port is always the destination pod's port
+func checkPolicies(policies []networkPolicy, self, peer endpoint, ingress bool, port int, protocol string) policyVerdict {
+	direction := "Egress"
+	portOwner := peer
+	if ingress {
+		direction = "Ingress"
+		portOwner = self
+	}
+	var verdict policyVerdict
+	var isolating []string
+	for _, p := range policies {
+		if !p.appliesTo(self, direction) {
+			continue
+		}
+		verdict.Isolated = true
+		isolating = append(isolating, p.String())
+		rules := p.Spec.Egress
+		if ingress {
+			rules = p.Spec.Ingress
+		}
+		if allows(rules, p.Metadata.Namespace, peer, ingress, port, protocol, portOwner) {
+			verdict.Allowed = true
+		}
+	}
+	if !verdict.Isolated {
+		verdict.Allowed = true
+	}
+	if !verdict.Allowed {
+		verdict.Blocking = isolating
+	}
+	return verdict
+}
+
+func parsePolicies(data []byte) ([]networkPolicy, error) {
+	var list struct {
+		Items []networkPolicy `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse network policies: %w", err)
+	}
+	return list.Items, nil
+}
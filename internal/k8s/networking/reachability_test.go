@@ -0,0 +1,271 @@
+package networking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// clusterClient answers kubectl calls from fixed objects keyed by namespace
+// and arguments
+type clusterClient struct {
+	mockClient
+	run     map[string]string // "ns: args" prefixes, ": args" without a namespace
+	objects map[string]string // "kind/name/ns"
+	applied []string
+	deleted []string // "kind/name/ns"
+}
+
+func (c *clusterClient) Apply(ctx context.Context, manifest string) (string, error) {
+	c.applied = append(c.applied, manifest)
+	return "", nil
+}
+
+func (c *clusterClient) Delete(ctx context.Context, resourceType, name, namespace string) (string, error) {
+	c.deleted = append(c.deleted, resourceType+"/"+name+"/"+namespace)
+	return "", nil
+}
+
+func (c *clusterClient) Run(ctx context.Context, args ...string) (string, error) {
+	return c.RunWithNamespace(ctx, "", args...)
+}
+
+func (c *clusterClient) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	key := namespace + ": " + strings.Join(args, " ")
+	for prefix, out := range c.run {
+		if strings.HasPrefix(key, prefix) {
+			return out, nil
+		}
+	}
+	return "", errors.New("not found: " + key)
+}
+
+func (c *clusterClient) GetJSON(ctx context.Context, resourceType, name, namespace string) ([]byte, error) {
+	if out, ok := c.objects[resourceType+"/"+name+"/"+namespace]; ok {
+		return []byte(out), nil
+	}
+	return nil, errors.New("not found")
+}
+
+const (
+	reachPods = `{"items": [
+		{"metadata": {"name": "web-7d9f", "namespace": "shop", "uid": "uid-web", "labels": {"app": "web"}},
+		 "spec": {"nodeName": "node-a"}, "status": {"phase": "Running", "podIP": "10.1.0.5"}},
+		{"metadata": {"name": "api-5c8b", "namespace": "shop", "labels": {"app": "api"}},
+		 "spec": {"containers": [{"name": "api", "ports": [{"name": "http", "containerPort": 8080}]}]},
+		 "status": {"phase": "Running", "podIP": "10.1.0.9"}}]}`
+	reachService   = `{"spec": {"type": "ClusterIP", "clusterIP": "10.0.0.12", "selector": {"app": "api"}, "ports": [{"port": 80, "targetPort": "http", "protocol": "TCP"}]}}`
+	reachEndpoints = `{"subsets": [{"addresses": [{"ip": "10.1.0.9", "targetRef": {"name": "api-5c8b"}}]}]}`
+	denyIngress    = `{"metadata": {"name": "default-deny", "namespace": "shop"}, "spec": {"podSelector": {}, "policyTypes": ["Ingress"]}}`
+	allowWebToAPI  = `{"metadata": {"name": "web-to-api", "namespace": "shop"}, "spec": {"podSelector": {"matchLabels": {"app": "api"}},
+		"ingress": [{"from": [{"podSelector": {"matchLabels": {"app": "web"}}}], "ports": [{"port": "http"}]}]}}`
+)
+
+func reachCluster(policies ...string) *clusterClient {
+	return &clusterClient{
+		run: map[string]string{
+			"shop: get pods -o json":            reachPods,
+			"shop: get networkpolicies -o json": `{"items": [` + strings.Join(policies, ",") + `]}`,
+		},
+		objects: map[string]string{
+			"service/api/shop":   reachService,
+			"endpoints/api/shop": reachEndpoints,
+		},
+	}
+}
+
+func checkReachability(t *testing.T, client *clusterClient, query string) *ReachabilityReport {
+	t.Helper()
+	viper.Set("kubernetes.netcheck.probe", false)
+	t.Cleanup(func() { viper.Set("kubernetes.netcheck.probe", nil) })
+	q, ok := ParseReachabilityQuery(query)
+	if !ok {
+		t.Fatalf("%q not parsed as a reachability question", query)
+	}
+	report, err := NewSubAgent(client, false).CheckReachability(context.Background(), q, "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestParseReachabilityQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  ReachabilityQuery
+	}{
+		{"can pod web reach service api on port 8080?", ReachabilityQuery{Source: "web", Target: "api", Port: 8080}},
+		{"Can frontend talk to api.payments:443", ReachabilityQuery{Source: "frontend", Target: "api", TargetNamespace: "payments", Port: 443}},
+		{"is payments/api reachable from shop/web", ReachabilityQuery{Source: "web", SourceNamespace: "shop", Target: "api", TargetNamespace: "payments"}},
+		{"can web connect to api.payments.svc.cluster.local in the shop namespace", ReachabilityQuery{Source: "web", SourceNamespace: "shop", Target: "api", TargetNamespace: "payments"}},
+		{"can pod web reach example.com", ReachabilityQuery{Source: "web", Target: "example.com", External: true}},
+	}
+	for _, tt := range tests {
+		got, ok := ParseReachabilityQuery(tt.query)
+		if !ok || got != tt.want {
+			t.Errorf("ParseReachabilityQuery(%q) = %+v, %v; want %+v", tt.query, got, ok, tt.want)
+		}
+	}
+
+	for _, query := range []string{"can i access secrets in prod", "can user alice access pods", "list services", "can pod web access secrets"} {
+		if _, ok := ParseReachabilityQuery(query); ok {
+			t.Errorf("ParseReachabilityQuery(%q) matched", query)
+		}
+	}
+}
+
+func TestReachabilityNamesBlockingPolicy(t *testing.T) {
+	report := checkReachability(t, reachCluster(denyIngress), "can web reach api")
+	if report.Verdict != VerdictBlocked {
+		t.Fatalf("verdict = %s (%s)", report.Verdict, report.Reason)
+	}
+	if len(report.BlockingPolicies) != 1 || report.BlockingPolicies[0] != "shop/default-deny" {
+		t.Errorf("blocking = %v", report.BlockingPolicies)
+	}
+	if !strings.Contains(report.Reason, "ingress to api-5c8b on 8080/TCP") {
+		t.Errorf("reason = %q", report.Reason)
+	}
+
+	// A policy allowing web on the named port opens the path
+	report = checkReachability(t, reachCluster(denyIngress, allowWebToAPI), "can web reach api")
+	if report.Verdict != VerdictReachable || report.TargetPort != "http" {
+		t.Errorf("verdict = %s (%s), target port %s", report.Verdict, report.Reason, report.TargetPort)
+	}
+}
+
+func TestReachabilityMissingEndpoints(t *testing.T) {
+	client := reachCluster()
+	client.objects["endpoints/api/shop"] = `{"subsets": []}`
+	client.objects["service/api/shop"] = strings.Replace(reachService, `"app": "api"`, `"app": "api-v2"`, 1)
+
+	report := checkReachability(t, client, "can web reach api")
+	if report.Verdict != VerdictNoEndpoints || !strings.Contains(report.Reason, "app=api-v2, which matches no pods") {
+		t.Errorf("verdict = %s (%s)", report.Verdict, report.Reason)
+	}
+
+	report = checkReachability(t, client, "can web reach billing")
+	if report.Verdict != VerdictNoService {
+		t.Errorf("verdict = %s (%s)", report.Verdict, report.Reason)
+	}
+	report = checkReachability(t, reachCluster(), "can web reach api on port 9090")
+	if report.Verdict != VerdictUnreachable || !strings.Contains(report.Reason, "exposes 80") {
+		t.Errorf("verdict = %s (%s)", report.Verdict, report.Reason)
+	}
+}
+
+func TestReachabilityEgressBlocksDNS(t *testing.T) {
+	egressToAPI := `{"metadata": {"name": "web-egress", "namespace": "shop"}, "spec": {"podSelector": {"matchLabels": {"app": "web"}},
+		"policyTypes": ["Egress"], "egress": [{"to": [{"podSelector": {"matchLabels": {"app": "api"}}}]}]}}`
+	client := reachCluster(egressToAPI)
+	client.run["kube-system: get pods -o json -l k8s-app=kube-dns"] = `{"items": [{"metadata": {"name": "coredns-1", "namespace": "kube-system", "labels": {"k8s-app": "kube-dns"}}}]}`
+
+	report := checkReachability(t, client, "can web reach api")
+	if report.Verdict != VerdictBlocked || !strings.Contains(report.Reason, "DNS lookups") {
+		t.Errorf("verdict = %s (%s)", report.Verdict, report.Reason)
+	}
+}
+
+func TestReachabilityLiveProbe(t *testing.T) {
+	orig := probePollInterval
+	probePollInterval = 0
+	t.Cleanup(func() { probePollInterval = orig })
+	viper.Set("kubernetes.netcheck.probe", true)
+	t.Cleanup(func() { viper.Set("kubernetes.netcheck.probe", nil) })
+
+	client := reachCluster()
+	client.run["shop: get pod clanker-netcheck-"] = "Succeeded"
+	client.run["shop: logs clanker-netcheck-"] = "dns=ok\ntcp=fail\nhttp=000\n"
+
+	q, _ := ParseReachabilityQuery("can web reach api")
+	report, err := NewSubAgent(client, false).CheckReachability(context.Background(), q, "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Verdict != VerdictUnreachable || !strings.Contains(report.Reason, "10.0.0.12:80 failed") {
+		t.Errorf("verdict = %s (%s)", report.Verdict, report.Reason)
+	}
+	if len(report.Checks) != 3 || !report.Checks[0].OK || report.Checks[1].OK {
+		t.Errorf("checks = %+v", report.Checks)
+	}
+	if out := report.String(); !strings.Contains(out, "tcp   FAIL 10.0.0.12:80") {
+		t.Errorf("report:\n%s", out)
+	}
+
+	// The probe is a pod of its own beside web-7d9f, owned by it and
+	// deleted afterwards
+	if len(client.applied) != 1 {
+		t.Fatalf("applied %d manifests, want the probe pod", len(client.applied))
+	}
+	var pod struct {
+		Metadata struct {
+			Name            string            `json:"name"`
+			Namespace       string            `json:"namespace"`
+			Labels          map[string]string `json:"labels"`
+			OwnerReferences []struct {
+				UID        string `json:"uid"`
+				Controller bool   `json:"controller"`
+			} `json:"ownerReferences"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName       string `json:"nodeName"`
+			ReadinessGates []any  `json:"readinessGates"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal([]byte(client.applied[0]), &pod); err != nil {
+		t.Fatal(err)
+	}
+	if pod.Metadata.Namespace != "shop" || pod.Metadata.Labels["app"] != "web" || pod.Spec.NodeName != "node-a" || len(pod.Spec.ReadinessGates) != 1 ||
+		len(pod.Metadata.OwnerReferences) != 1 || pod.Metadata.OwnerReferences[0].UID != "uid-web" || !pod.Metadata.OwnerReferences[0].Controller {
+		t.Errorf("probe pod = %+v", pod)
+	}
+	if want := "pod/" + pod.Metadata.Name + "/shop"; len(client.deleted) != 1 || client.deleted[0] != want {
+		t.Errorf("deleted = %v, want %s", client.deleted, want)
+	}
+}
+
+func TestReachabilityProbeDryRun(t *testing.T) {
+	viper.Set("kubernetes.netcheck.probe", true)
+	viper.Set("dry_run", true)
+	t.Cleanup(func() {
+		viper.Set("kubernetes.netcheck.probe", nil)
+		viper.Set("dry_run", nil)
+	})
+
+	client := reachCluster()
+	q, _ := ParseReachabilityQuery("can web reach api")
+	report, err := NewSubAgent(client, false).CheckReachability(context.Background(), q, "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.applied) != 0 || len(client.deleted) != 0 {
+		t.Errorf("dry run applied %d and deleted %v", len(client.applied), client.deleted)
+	}
+	if !strings.Contains(report.String(), "Live checks skipped: dry run") {
+		t.Errorf("report:\n%s", report.String())
+	}
+}
+
+func TestPortAllowedNamedAndRange(t *testing.T) {
+	owner := endpoint{NamedPorts: map[string]int{"http": 8080}}
+	tests := []struct {
+		ports []policyPort
+		port  int
+		proto string
+		want  bool
+	}{
+		{nil, 53, "UDP", true},
+		{[]policyPort{{Port: "http"}}, 8080, "TCP", true},
+		{[]policyPort{{Port: "http"}}, 8080, "UDP", false},
+		{[]policyPort{{Port: float64(8000), EndPort: 9000}}, 8080, "TCP", true},
+		{[]policyPort{{Port: float64(80)}}, 8080, "TCP", false},
+	}
+	for _, tt := range tests {
+		if got := portAllowed(tt.ports, tt.port, tt.proto, owner); got != tt.want {
+			t.Errorf("portAllowed(%+v, %d, %s) = %v", tt.ports, tt.port, tt.proto, got)
+		}
+	}
+}
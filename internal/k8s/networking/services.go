@@ -270,9 +270,7 @@ func (m *ServiceManager) parseServiceList(data []byte) ([]ServiceInfo, error) {
 	for _, item := range list.Items {
 		svc, err := m.parseService(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[services] failed to parse service: %v\n", err)
-			}
+			logger.Debugf("failed to parse service: %v", err)
 			continue
 		}
 		services = append(services, *svc)
@@ -397,9 +395,7 @@ func (m *ServiceManager) parseEndpointList(data []byte) ([]EndpointInfo, error)
 	for _, item := range list.Items {
 		ep, err := m.parseEndpoint(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[services] failed to parse endpoint: %v\n", err)
-			}
+			logger.Debugf("failed to parse endpoint: %v", err)
 			continue
 		}
 		endpoints = append(endpoints, *ep)
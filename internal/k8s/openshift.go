@@ -0,0 +1,98 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// OpenShift API groups that vanilla Kubernetes never serves
+const (
+	openShiftRouteGroup    = "route.openshift.io"
+	openShiftProjectGroup  = "project.openshift.io"
+	openShiftSecurityGroup = "security.openshift.io"
+	openShiftAppsGroup     = "apps.openshift.io"
+)
+
+// openShiftContextPattern matches the context names `oc login` writes:
+// <project>/<api-server-host-with-dashes>:<port>/<user>
+var openShiftContextPattern = regexp.MustCompile(`^[^/]*/api-[^/]+:\d+/[^/]+$`)
+
+// openShiftNamePattern matches OpenShift in context or cluster names
+var openShiftNamePattern = regexp.MustCompile(`\b(openshift|okd|ocp|rosa)\b`)
+
+// OpenShiftInfo records which OpenShift APIs a cluster serves
+type OpenShiftInfo struct {
+	Routes            bool `json:"routes"`
+	Projects          bool `json:"projects"`
+	SCCs              bool `json:"sccs"`
+	DeploymentConfigs bool `json:"deploymentConfigs"`
+}
+
+// IsOpenShift reports whether the cluster is OpenShift or OKD. Routes alone
+// can be installed on vanilla clusters, so projects or SCCs must be present
+// as well.
+func (i OpenShiftInfo) IsOpenShift() bool {
+	return i.Routes && (i.Projects || i.SCCs)
+}
+
+// ParseOpenShiftAPIGroups reads `kubectl api-versions` output
+func ParseOpenShiftAPIGroups(apiVersions string) OpenShiftInfo {
+	var info OpenShiftInfo
+	for _, line := range strings.Split(apiVersions, "\n") {
+		group, _, _ := strings.Cut(strings.TrimSpace(line), "/")
+		switch group {
+		case openShiftRouteGroup:
+			info.Routes = true
+		case openShiftProjectGroup:
+			info.Projects = true
+		case openShiftSecurityGroup:
+			info.SCCs = true
+		case openShiftAppsGroup:
+			info.DeploymentConfigs = true
+		}
+	}
+	return info
+}
+
+// DetectOpenShift asks the API server which OpenShift groups it serves
+func (c *Client) DetectOpenShift(ctx context.Context) (OpenShiftInfo, error) {
+	out, err := c.RunWithNamespace(ctx, "all", "api-versions")
+	if err != nil {
+		return OpenShiftInfo{}, fmt.Errorf("failed to list API versions: %w", err)
+	}
+	return ParseOpenShiftAPIGroups(out), nil
+}
+
+// UseOcIfAvailable switches the client to the oc CLI when it is installed
+// and no binary was configured. kubectl works against OpenShift too, but oc
+// understands projects, `oc adm` and DeploymentConfig rollouts. It reports
+// whether oc is now in use.
+func (c *Client) UseOcIfAvailable() bool {
+	c.binaryMu.Lock()
+	defer c.binaryMu.Unlock()
+	if c.binary != "" {
+		return c.binary == "oc"
+	}
+	if !IsOcAvailable() {
+		return false
+	}
+	c.binary = "oc"
+	return true
+}
+
+// IsOcAvailable checks if the OpenShift CLI is installed
+func IsOcAvailable() bool {
+	_, err := exec.LookPath("oc")
+	return err == nil
+}
+
+// isOpenShiftContext matches kubeconfig contexts created by `oc login` or
+// named after OpenShift
+func isOpenShiftContext(contextName string) bool {
+	contextLower := strings.ToLower(contextName)
+	return openShiftContextPattern.MatchString(contextLower) ||
+		openShiftNamePattern.MatchString(contextLower)
+}
@@ -0,0 +1,268 @@
+package openshift
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/log"
+)
+
+var logger = log.New("k8s.openshift")
+
+// SubAgent handles OpenShift-specific queries: Routes, DeploymentConfigs,
+// projects and SecurityContextConstraints
+type SubAgent struct {
+	client K8sClient
+	debug  bool
+}
+
+// NewSubAgent creates a new openshift sub-agent
+func NewSubAgent(client K8sClient, debug bool) *SubAgent {
+	return &SubAgent{
+		client: client,
+		debug:  debug,
+	}
+}
+
+// queryAnalysis contains parsed query information
+type queryAnalysis struct {
+	Resource  ResourceType
+	Name      string
+	Namespace string
+}
+
+var (
+	routePattern            = regexp.MustCompile(`\broutes?\b`)
+	deploymentConfigPattern = regexp.MustCompile(`\b(deploymentconfigs?|deployment\s+configs?|dc)\b`)
+	sccPattern              = regexp.MustCompile(`\b(sccs?|security\s*context\s*constraints?)\b`)
+	projectPattern          = regexp.MustCompile(`\bprojects?\b`)
+	namespacePattern        = regexp.MustCompile(`\b(?:in|within)\s+(?:the\s+)?(?:namespace\s+|project\s+|ns\s+)?([a-z0-9][a-z0-9-]*)`)
+	describePattern         = regexp.MustCompile(`\b(?:describe|show|details? (?:of|for)|inspect)\s+(?:the\s+)?(?:route|deploymentconfig|dc|scc)\s+([a-z0-9][a-z0-9.-]*)`)
+)
+
+// MatchesQuery reports whether a query is about an OpenShift-only resource.
+// Projects only count when the query names OpenShift, since "project" means
+// something else on GCP.
+func MatchesQuery(query string) bool {
+	q := strings.ToLower(query)
+	if routePattern.MatchString(q) || deploymentConfigPattern.MatchString(q) || sccPattern.MatchString(q) {
+		return true
+	}
+	return projectPattern.MatchString(q) && strings.Contains(q, "openshift")
+}
+
+// HandleQuery processes an OpenShift query and returns the result
+func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
+	logger.Debugf("handling query: %s", query)
+
+	analysis := s.analyzeQuery(query)
+	if analysis.Namespace == "" {
+		analysis.Namespace = opts.Namespace
+	}
+
+	logger.Debugf("analysis: resource=%s, name=%s, namespace=%s", analysis.Resource, analysis.Name, analysis.Namespace)
+
+	if analysis.Name != "" {
+		return s.handleDescribe(ctx, analysis)
+	}
+
+	switch analysis.Resource {
+	case ResourceRoute:
+		return s.handleRoutes(ctx, analysis)
+	case ResourceDeploymentConfig:
+		return s.handleDeploymentConfigs(ctx, analysis)
+	case ResourceSCC:
+		return s.handleSCCs(ctx)
+	default:
+		return s.handleProjects(ctx)
+	}
+}
+
+// analyzeQuery picks the resource and optional name and namespace
+func (s *SubAgent) analyzeQuery(query string) queryAnalysis {
+	q := strings.ToLower(query)
+	analysis := queryAnalysis{Resource: ResourceProject}
+
+	switch {
+	case deploymentConfigPattern.MatchString(q):
+		analysis.Resource = ResourceDeploymentConfig
+	case routePattern.MatchString(q):
+		analysis.Resource = ResourceRoute
+	case sccPattern.MatchString(q):
+		analysis.Resource = ResourceSCC
+	}
+
+	if m := namespacePattern.FindStringSubmatch(q); m != nil && !isClusterWord(m[1]) {
+		analysis.Namespace = m[1]
+	}
+	if m := describePattern.FindStringSubmatch(q); m != nil && !isClusterWord(m[1]) && !isFillerWord(m[1]) {
+		analysis.Name = m[1]
+	}
+	return analysis
+}
+
+// handleRoutes lists routes with their host, backend and TLS termination
+func (s *SubAgent) handleRoutes(ctx context.Context, analysis queryAnalysis) (*Response, error) {
+	routes, err := ListRoutes(ctx, s.client, analysis.Namespace)
+	if err != nil {
+		return errorResponse(err), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Routes in %s\n\n", scopeName(analysis.Namespace)))
+	if len(routes) == 0 {
+		sb.WriteString("No routes found.\n")
+	}
+	notAdmitted := 0
+	for _, r := range routes {
+		url := "http://" + r.Host
+		if r.Termination != "" {
+			url = "https://" + r.Host
+		}
+		backend := r.Service
+		if r.TargetPort != "" {
+			backend += ":" + r.TargetPort
+		}
+		sb.WriteString(fmt.Sprintf("  %s/%s  %s%s -> %s", r.Namespace, r.Name, url, r.Path, backend))
+		if r.Termination != "" {
+			sb.WriteString("  tls=" + r.Termination)
+		}
+		if !r.Admitted {
+			sb.WriteString("  [not admitted]")
+			notAdmitted++
+		}
+		sb.WriteString("\n")
+	}
+
+	message := fmt.Sprintf("%d route(s)", len(routes))
+	if notAdmitted > 0 {
+		message += fmt.Sprintf(", %d not admitted by a router", notAdmitted)
+	}
+	return &Response{Type: ResponseTypeResult, Data: sb.String(), Message: message}, nil
+}
+
+// handleDeploymentConfigs lists DeploymentConfigs with rollout state
+func (s *SubAgent) handleDeploymentConfigs(ctx context.Context, analysis queryAnalysis) (*Response, error) {
+	configs, err := ListDeploymentConfigs(ctx, s.client, analysis.Namespace)
+	if err != nil {
+		return errorResponse(err), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("DeploymentConfigs in %s\n\n", scopeName(analysis.Namespace)))
+	if len(configs) == 0 {
+		sb.WriteString("No DeploymentConfigs found.\n")
+	}
+	for _, dc := range configs {
+		sb.WriteString(fmt.Sprintf("  %s/%s  ready %d/%d  revision %d", dc.Namespace, dc.Name, dc.ReadyReplicas, dc.Replicas, dc.LatestVersion))
+		if len(dc.Triggers) > 0 {
+			sb.WriteString("  triggers=" + strings.Join(dc.Triggers, ","))
+		}
+		if dc.Paused {
+			sb.WriteString("  [paused]")
+		}
+		sb.WriteString("\n")
+	}
+	if len(configs) > 0 {
+		sb.WriteString("\nDeploymentConfigs are deprecated since OpenShift 4.14; consider migrating them to Deployments.\n")
+	}
+
+	return &Response{
+		Type:    ResponseTypeResult,
+		Data:    sb.String(),
+		Message: fmt.Sprintf("%d DeploymentConfig(s)", len(configs)),
+	}, nil
+}
+
+// handleSCCs lists SecurityContextConstraints and flags broad grants
+func (s *SubAgent) handleSCCs(ctx context.Context) (*Response, error) {
+	sccs, err := ListSCCs(ctx, s.client)
+	if err != nil {
+		return errorResponse(err), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("SecurityContextConstraints (%d)\n\n", len(sccs)))
+	for _, scc := range sccs {
+		sb.WriteString(fmt.Sprintf("  %-28s priority=%d runAsUser=%s", scc.Name, scc.Priority, scc.RunAsUser))
+		if scc.Privileged {
+			sb.WriteString("  privileged")
+		}
+		if scc.HostNetwork {
+			sb.WriteString("  hostNetwork")
+		}
+		if len(scc.Groups) > 0 {
+			sb.WriteString("  groups=" + strings.Join(scc.Groups, ","))
+		}
+		sb.WriteString("\n")
+	}
+	warnings := SCCWarnings(sccs)
+	if len(warnings) > 0 {
+		sb.WriteString("\nWarnings:\n")
+		for _, w := range warnings {
+			sb.WriteString("  " + w + "\n")
+		}
+	}
+
+	return &Response{
+		Type:    ResponseTypeResult,
+		Data:    sb.String(),
+		Message: fmt.Sprintf("%d SCC(s), %d warning(s)", len(sccs), len(warnings)),
+	}, nil
+}
+
+// handleProjects lists projects the caller can see. Unlike namespaces, the
+// project list is filtered by the caller's access.
+func (s *SubAgent) handleProjects(ctx context.Context) (*Response, error) {
+	output, err := s.client.RunWithNamespace(ctx, "all", "get", "projects")
+	if err != nil {
+		return errorResponse(fmt.Errorf("failed to list projects: %w", err)), nil
+	}
+	return &Response{Type: ResponseTypeResult, Data: output}, nil
+}
+
+// handleDescribe describes a single named resource
+func (s *SubAgent) handleDescribe(ctx context.Context, analysis queryAnalysis) (*Response, error) {
+	resource := string(analysis.Resource)
+	if analysis.Resource == ResourceSCC {
+		resource = "securitycontextconstraints"
+	}
+	namespace := analysis.Namespace
+	if analysis.Resource == ResourceSCC || analysis.Resource == ResourceProject {
+		namespace = "all"
+	}
+	output, err := s.client.RunWithNamespace(ctx, namespace, "describe", resource, analysis.Name)
+	if err != nil {
+		return errorResponse(fmt.Errorf("failed to describe %s %s: %w", analysis.Resource, analysis.Name, err)), nil
+	}
+	return &Response{Type: ResponseTypeResult, Data: output}, nil
+}
+
+func errorResponse(err error) *Response {
+	return &Response{Type: ResponseTypeError, Message: err.Error(), Error: err}
+}
+
+func scopeName(namespace string) string {
+	if namespace == "" {
+		return "all projects"
+	}
+	return "project " + namespace
+}
+
+func isClusterWord(word string) bool {
+	switch word {
+	case "cluster", "all", "any", "every", "my", "openshift":
+		return true
+	}
+	return false
+}
+
+func isFillerWord(word string) bool {
+	switch word {
+	case "for", "in", "of", "on", "with", "that", "which":
+		return true
+	}
+	return false
+}
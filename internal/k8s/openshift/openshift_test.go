@@ -0,0 +1,199 @@
+package openshift
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// mockK8sClient implements K8sClient interface for testing. RunJSON answers
+// from jsonByResource keyed on the resource argument.
+type mockK8sClient struct {
+	output         string
+	lastNamespace  string
+	lastArgs       []string
+	jsonByResource map[string]string
+}
+
+func (m *mockK8sClient) Run(ctx context.Context, args ...string) (string, error) {
+	m.lastArgs = args
+	return m.output, nil
+}
+
+func (m *mockK8sClient) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	m.lastNamespace = namespace
+	m.lastArgs = args
+	return m.output, nil
+}
+
+func (m *mockK8sClient) RunJSON(ctx context.Context, args ...string) ([]byte, error) {
+	m.lastArgs = args
+	if out, ok := m.jsonByResource[args[1]]; ok {
+		return []byte(out), nil
+	}
+	return []byte(`{"items":[]}`), nil
+}
+
+func testClient() *mockK8sClient {
+	return &mockK8sClient{jsonByResource: map[string]string{
+		"routes": `{"items":[
+			{"metadata":{"name":"web","namespace":"shop"},
+			 "spec":{"host":"web-shop.apps.example.com","to":{"kind":"Service","name":"web"},"port":{"targetPort":"8080-tcp"},"tls":{"termination":"edge"}},
+			 "status":{"ingress":[{"conditions":[{"type":"Admitted","status":"True"}]}]}},
+			{"metadata":{"name":"api","namespace":"shop"},
+			 "spec":{"host":"api.example.com","path":"/v1","to":{"kind":"Service","name":"api"},"port":{"targetPort":8080}},
+			 "status":{"ingress":[{"conditions":[{"type":"Admitted","status":"False"}]}]}}
+		]}`,
+		"deploymentconfigs": `{"items":[
+			{"metadata":{"name":"legacy","namespace":"shop"},
+			 "spec":{"replicas":3,"triggers":[{"type":"ConfigChange"},{"type":"ImageChange"}]},
+			 "status":{"latestVersion":7,"readyReplicas":2}}
+		]}`,
+		"securitycontextconstraints": `{"items":[
+			{"metadata":{"name":"restricted-v2"},"allowPrivilegedContainer":false,"runAsUser":{"type":"MustRunAsRange"},"groups":[]},
+			{"metadata":{"name":"privileged"},"priority":null,"allowPrivilegedContainer":true,"runAsUser":{"type":"RunAsAny"},"users":["system:admin"],"groups":["system:cluster-admins"]},
+			{"metadata":{"name":"anyuid"},"priority":10,"allowPrivilegedContainer":false,"runAsUser":{"type":"RunAsAny"},"groups":["system:authenticated"]}
+		]}`,
+	}}
+}
+
+func TestMatchesQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"list routes in shop", true},
+		{"show deploymentconfigs", true},
+		{"which deployment configs are paused", true},
+		{"audit SCCs", true},
+		{"list security context constraints", true},
+		{"list openshift projects", true},
+		{"list projects", false},
+		{"show pods in gcp project foo", false},
+		{"show me the routing table", false},
+		{"list deployments", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchesQuery(tt.query); got != tt.want {
+			t.Errorf("MatchesQuery(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeQuery(t *testing.T) {
+	s := NewSubAgent(&mockK8sClient{}, false)
+
+	tests := []struct {
+		query     string
+		resource  ResourceType
+		name      string
+		namespace string
+	}{
+		{query: "list routes in shop", resource: ResourceRoute, namespace: "shop"},
+		{query: "show routes in the project payments", resource: ResourceRoute, namespace: "payments"},
+		{query: "describe route web in shop", resource: ResourceRoute, name: "web", namespace: "shop"},
+		{query: "show the route for web", resource: ResourceRoute},
+		{query: "list dc in all namespaces", resource: ResourceDeploymentConfig},
+		{query: "show scc anyuid", resource: ResourceSCC, name: "anyuid"},
+		{query: "list openshift projects", resource: ResourceProject},
+	}
+
+	for _, tt := range tests {
+		got := s.analyzeQuery(tt.query)
+		if got.Resource != tt.resource || got.Name != tt.name || got.Namespace != tt.namespace {
+			t.Errorf("analyzeQuery(%q) = %+v, want resource=%s name=%s namespace=%s",
+				tt.query, got, tt.resource, tt.name, tt.namespace)
+		}
+	}
+}
+
+func TestListRoutes(t *testing.T) {
+	routes, err := ListRoutes(context.Background(), testClient(), "")
+	if err != nil {
+		t.Fatalf("ListRoutes: %v", err)
+	}
+	if len(routes) != 2 {
+		t.Fatalf("got %d routes, want 2", len(routes))
+	}
+
+	api, web := routes[0], routes[1]
+	if api.Name != "api" || api.TargetPort != "8080" || api.Termination != "" || api.Admitted {
+		t.Errorf("api route = %+v", api)
+	}
+	if web.Service != "web" || web.TargetPort != "8080-tcp" || web.Termination != "edge" || !web.Admitted {
+		t.Errorf("web route = %+v", web)
+	}
+}
+
+func TestHandleRoutes(t *testing.T) {
+	client := testClient()
+	s := NewSubAgent(client, false)
+
+	resp, err := s.HandleQuery(context.Background(), "list routes in shop", QueryOptions{})
+	if err != nil {
+		t.Fatalf("HandleQuery: %v", err)
+	}
+	if strings.Join(client.lastArgs, " ") != "get routes -n shop" {
+		t.Errorf("args = %v", client.lastArgs)
+	}
+	out := resp.Data.(string)
+	for _, want := range []string{
+		"https://web-shop.apps.example.com -> web:8080-tcp  tls=edge",
+		"http://api.example.com/v1 -> api:8080  [not admitted]",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if resp.Message != "2 route(s), 1 not admitted by a router" {
+		t.Errorf("message = %q", resp.Message)
+	}
+}
+
+func TestHandleDeploymentConfigs(t *testing.T) {
+	s := NewSubAgent(testClient(), false)
+
+	resp, err := s.HandleQuery(context.Background(), "show deploymentconfigs", QueryOptions{Namespace: "shop"})
+	if err != nil {
+		t.Fatalf("HandleQuery: %v", err)
+	}
+	out := resp.Data.(string)
+	if !strings.Contains(out, "shop/legacy  ready 2/3  revision 7  triggers=ConfigChange,ImageChange") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	if !strings.Contains(out, "deprecated") {
+		t.Errorf("expected deprecation note:\n%s", out)
+	}
+}
+
+func TestListSCCsAndWarnings(t *testing.T) {
+	sccs, err := ListSCCs(context.Background(), testClient())
+	if err != nil {
+		t.Fatalf("ListSCCs: %v", err)
+	}
+	if len(sccs) != 3 || sccs[0].Name != "privileged" {
+		t.Fatalf("expected privileged SCC first, got %+v", sccs)
+	}
+
+	warnings := SCCWarnings(sccs)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "SCC anyuid allows running as any UID to every member of system:authenticated") {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
+
+func TestHandleDescribe(t *testing.T) {
+	client := &mockK8sClient{output: "Name: anyuid"}
+	s := NewSubAgent(client, false)
+
+	resp, err := s.HandleQuery(context.Background(), "describe scc anyuid", QueryOptions{Namespace: "shop"})
+	if err != nil {
+		t.Fatalf("HandleQuery: %v", err)
+	}
+	if resp.Data != "Name: anyuid" {
+		t.Errorf("data = %v", resp.Data)
+	}
+	if client.lastNamespace != "all" || strings.Join(client.lastArgs, " ") != "describe securitycontextconstraints anyuid" {
+		t.Errorf("namespace=%q args=%v", client.lastNamespace, client.lastArgs)
+	}
+}
@@ -0,0 +1,234 @@
+package openshift
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// routeList is the subset of `get routes -o json` used here
+type routeList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Host string `json:"host"`
+			Path string `json:"path"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+			Port *struct {
+				TargetPort interface{} `json:"targetPort"`
+			} `json:"port"`
+			TLS *struct {
+				Termination string `json:"termination"`
+			} `json:"tls"`
+		} `json:"spec"`
+		Status struct {
+			Ingress []struct {
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"ingress"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// ListRoutes returns the routes in a namespace, or every namespace when
+// namespace is empty
+func ListRoutes(ctx context.Context, client K8sClient, namespace string) ([]RouteInfo, error) {
+	data, err := client.RunJSON(ctx, scopedArgs("routes", namespace)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+	var list routeList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse routes: %w", err)
+	}
+
+	routes := make([]RouteInfo, 0, len(list.Items))
+	for _, item := range list.Items {
+		route := RouteInfo{
+			Name:      item.Metadata.Name,
+			Namespace: item.Metadata.Namespace,
+			Host:      item.Spec.Host,
+			Path:      item.Spec.Path,
+			Service:   item.Spec.To.Name,
+		}
+		if item.Spec.Port != nil && item.Spec.Port.TargetPort != nil {
+			route.TargetPort = fmt.Sprint(item.Spec.Port.TargetPort)
+		}
+		if item.Spec.TLS != nil {
+			route.Termination = item.Spec.TLS.Termination
+		}
+		// A route is served once any router has admitted it
+		for _, ingress := range item.Status.Ingress {
+			for _, cond := range ingress.Conditions {
+				if cond.Type == "Admitted" && cond.Status == "True" {
+					route.Admitted = true
+				}
+			}
+		}
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Namespace != routes[j].Namespace {
+			return routes[i].Namespace < routes[j].Namespace
+		}
+		return routes[i].Name < routes[j].Name
+	})
+	return routes, nil
+}
+
+// deploymentConfigList is the subset of `get deploymentconfigs -o json`
+// used here
+type deploymentConfigList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Replicas int  `json:"replicas"`
+			Paused   bool `json:"paused"`
+			Triggers []struct {
+				Type string `json:"type"`
+			} `json:"triggers"`
+		} `json:"spec"`
+		Status struct {
+			LatestVersion int `json:"latestVersion"`
+			ReadyReplicas int `json:"readyReplicas"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// ListDeploymentConfigs returns the DeploymentConfigs in a namespace, or
+// every namespace when namespace is empty
+func ListDeploymentConfigs(ctx context.Context, client K8sClient, namespace string) ([]DeploymentConfigInfo, error) {
+	data, err := client.RunJSON(ctx, scopedArgs("deploymentconfigs", namespace)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deploymentconfigs: %w", err)
+	}
+	var list deploymentConfigList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse deploymentconfigs: %w", err)
+	}
+
+	configs := make([]DeploymentConfigInfo, 0, len(list.Items))
+	for _, item := range list.Items {
+		dc := DeploymentConfigInfo{
+			Name:          item.Metadata.Name,
+			Namespace:     item.Metadata.Namespace,
+			Replicas:      item.Spec.Replicas,
+			ReadyReplicas: item.Status.ReadyReplicas,
+			LatestVersion: item.Status.LatestVersion,
+			Paused:        item.Spec.Paused,
+		}
+		for _, trigger := range item.Spec.Triggers {
+			dc.Triggers = append(dc.Triggers, trigger.Type)
+		}
+		configs = append(configs, dc)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		if configs[i].Namespace != configs[j].Namespace {
+			return configs[i].Namespace < configs[j].Namespace
+		}
+		return configs[i].Name < configs[j].Name
+	})
+	return configs, nil
+}
+
+// sccList is the subset of `get scc -o json` used here. SCC fields sit at
+// the top level of the object rather than under spec.
+type sccList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Priority                 *int     `json:"priority"`
+		AllowPrivilegedContainer bool     `json:"allowPrivilegedContainer"`
+		AllowHostNetwork         bool     `json:"allowHostNetwork"`
+		Users                    []string `json:"users"`
+		Groups                   []string `json:"groups"`
+		Volumes                  []string `json:"volumes"`
+		RunAsUser                struct {
+			Type string `json:"type"`
+		} `json:"runAsUser"`
+	} `json:"items"`
+}
+
+// ListSCCs returns the cluster's SecurityContextConstraints, most
+// permissive first
+func ListSCCs(ctx context.Context, client K8sClient) ([]SCCInfo, error) {
+	data, err := client.RunJSON(ctx, "get", "securitycontextconstraints")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list securitycontextconstraints: %w", err)
+	}
+	var list sccList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse securitycontextconstraints: %w", err)
+	}
+
+	sccs := make([]SCCInfo, 0, len(list.Items))
+	for _, item := range list.Items {
+		scc := SCCInfo{
+			Name:        item.Metadata.Name,
+			Privileged:  item.AllowPrivilegedContainer,
+			HostNetwork: item.AllowHostNetwork,
+			RunAsUser:   item.RunAsUser.Type,
+			Users:       item.Users,
+			Groups:      item.Groups,
+			Volumes:     item.Volumes,
+		}
+		if item.Priority != nil {
+			scc.Priority = *item.Priority
+		}
+		sccs = append(sccs, scc)
+	}
+	sort.SliceStable(sccs, func(i, j int) bool {
+		if sccs[i].Privileged != sccs[j].Privileged {
+			return sccs[i].Privileged
+		}
+		return sccs[i].Name < sccs[j].Name
+	})
+	return sccs, nil
+}
+
+// SCCWarnings flags SCCs that grant privileged or host access to broad
+// groups, the usual way workloads escape OpenShift's restricted default
+func SCCWarnings(sccs []SCCInfo) []string {
+	var warnings []string
+	for _, scc := range sccs {
+		if !scc.Privileged && !scc.HostNetwork && scc.RunAsUser != "RunAsAny" {
+			continue
+		}
+		for _, group := range scc.Groups {
+			if group == "system:authenticated" || group == "system:serviceaccounts" {
+				warnings = append(warnings, fmt.Sprintf("SCC %s allows %s to every member of %s", scc.Name, sccGrant(scc), group))
+			}
+		}
+	}
+	return warnings
+}
+
+func sccGrant(scc SCCInfo) string {
+	switch {
+	case scc.Privileged:
+		return "privileged containers"
+	case scc.HostNetwork:
+		return "host networking"
+	default:
+		return "running as any UID"
+	}
+}
+
+func scopedArgs(resource, namespace string) []string {
+	if namespace == "" {
+		return []string{"get", resource, "-A"}
+	}
+	return []string{"get", resource, "-n", namespace}
+}
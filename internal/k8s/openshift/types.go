@@ -0,0 +1,77 @@
+package openshift
+
+import "context"
+
+// ResponseType indicates the type of response
+type ResponseType string
+
+const (
+	ResponseTypeResult ResponseType = "result"
+	ResponseTypeError  ResponseType = "error"
+)
+
+// ResourceType is the OpenShift resource a query is about
+type ResourceType string
+
+const (
+	ResourceRoute            ResourceType = "route"
+	ResourceDeploymentConfig ResourceType = "deploymentconfig"
+	ResourceProject          ResourceType = "project"
+	ResourceSCC              ResourceType = "scc"
+)
+
+// K8sClient defines the interface for kubectl or oc operations needed by
+// the openshift sub-agent
+type K8sClient interface {
+	Run(ctx context.Context, args ...string) (string, error)
+	RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error)
+	RunJSON(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// QueryOptions contains options for OpenShift queries
+type QueryOptions struct {
+	Namespace string
+}
+
+// Response from the openshift sub-agent
+type Response struct {
+	Type    ResponseType
+	Data    interface{}
+	Message string
+	Error   error
+}
+
+// RouteInfo summarises a route.openshift.io/v1 Route
+type RouteInfo struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	Host        string `json:"host"`
+	Path        string `json:"path,omitempty"`
+	Service     string `json:"service"`
+	TargetPort  string `json:"targetPort,omitempty"`
+	Termination string `json:"termination,omitempty"` // edge, passthrough, reencrypt; empty for plain HTTP
+	Admitted    bool   `json:"admitted"`
+}
+
+// DeploymentConfigInfo summarises an apps.openshift.io/v1 DeploymentConfig
+type DeploymentConfigInfo struct {
+	Name          string   `json:"name"`
+	Namespace     string   `json:"namespace"`
+	Replicas      int      `json:"replicas"`
+	ReadyReplicas int      `json:"readyReplicas"`
+	LatestVersion int      `json:"latestVersion"`
+	Triggers      []string `json:"triggers,omitempty"`
+	Paused        bool     `json:"paused,omitempty"`
+}
+
+// SCCInfo summarises a security.openshift.io/v1 SecurityContextConstraints
+type SCCInfo struct {
+	Name        string   `json:"name"`
+	Priority    int      `json:"priority"`
+	Privileged  bool     `json:"privileged"`
+	HostNetwork bool     `json:"hostNetwork"`
+	RunAsUser   string   `json:"runAsUser"`
+	Users       []string `json:"users,omitempty"`
+	Groups      []string `json:"groups,omitempty"`
+	Volumes     []string `json:"volumes,omitempty"`
+}
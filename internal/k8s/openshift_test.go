@@ -0,0 +1,73 @@
+package k8s
+
+import "testing"
+
+func TestParseOpenShiftAPIGroups(t *testing.T) {
+	apiVersions := `apps/v1
+apps.openshift.io/v1
+project.openshift.io/v1
+route.openshift.io/v1
+security.openshift.io/v1
+v1
+`
+	info := ParseOpenShiftAPIGroups(apiVersions)
+	if !info.Routes || !info.Projects || !info.SCCs || !info.DeploymentConfigs {
+		t.Errorf("ParseOpenShiftAPIGroups = %+v, want all groups", info)
+	}
+	if !info.IsOpenShift() {
+		t.Error("expected OpenShift")
+	}
+
+	vanilla := ParseOpenShiftAPIGroups("apps/v1\nnetworking.k8s.io/v1\nv1\n")
+	if vanilla.IsOpenShift() {
+		t.Errorf("vanilla cluster detected as OpenShift: %+v", vanilla)
+	}
+
+	// The Route CRD alone (e.g. from a MicroShift-style add-on) is not enough
+	routesOnly := ParseOpenShiftAPIGroups("route.openshift.io/v1\nv1\n")
+	if routesOnly.IsOpenShift() {
+		t.Errorf("routes-only cluster detected as OpenShift: %+v", routesOnly)
+	}
+}
+
+func TestIsOpenShiftContext(t *testing.T) {
+	tests := []struct {
+		contextName string
+		want        bool
+	}{
+		{"default/api-ocp-example-com:6443/kube:admin", true},
+		{"shop/api-prod-aws-example-com:6443/alice", true},
+		{"openshift-prod", true},
+		{"my-okd-lab", true},
+		{"rosa-staging", true},
+		{"bookdev", false},
+		{"arn:aws:eks:us-east-1:123456789012:cluster/ocpx", false},
+		{"minikube", false},
+	}
+
+	for _, tt := range tests {
+		if got := isOpenShiftContext(tt.contextName); got != tt.want {
+			t.Errorf("isOpenShiftContext(%q) = %v, want %v", tt.contextName, got, tt.want)
+		}
+	}
+}
+
+func TestClientBinary(t *testing.T) {
+	client := NewClient("", "", false)
+	if got := client.Binary(); got != "kubectl" {
+		t.Errorf("default binary = %q, want kubectl", got)
+	}
+
+	client.SetBinary("oc")
+	if got := client.Binary(); got != "oc" {
+		t.Errorf("binary = %q, want oc", got)
+	}
+	if !client.UseOcIfAvailable() {
+		t.Error("UseOcIfAvailable should keep an explicit oc binary")
+	}
+
+	client.SetBinary("kubectl")
+	if client.UseOcIfAvailable() {
+		t.Error("UseOcIfAvailable should not override an explicit kubectl binary")
+	}
+}
@@ -0,0 +1,109 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s/cluster"
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+)
+
+// PatchIntent is a parsed "patch and reboot all nodes of cluster X" request
+type PatchIntent struct {
+	ClusterName string
+	ClusterType ClusterType // empty when the query names no provider
+}
+
+// patchSignals are the phrases that make a node question an OS patching
+// request rather than a kubectl patch or a scaling change
+var patchSignals = []string{
+	"reboot", "os patch", "os update", "security update", "security patch",
+	"kernel update", "patch the nodes", "patch all nodes", "patch all the nodes",
+	"patch nodes", "patch the worker", "rotate the ami", "rotate ami", "ami rotation",
+	"latest ami", "node image", "apt upgrade", "yum update",
+}
+
+// ParsePatchQuery recognizes node OS patching requests such as "patch and
+// reboot all nodes of cluster prod one at a time" or "roll the eks cluster
+// prod's node groups onto the latest AMI"
+func ParsePatchQuery(query string) (PatchIntent, bool) {
+	q := strings.ToLower(query)
+	if !strings.Contains(q, "node") || !containsAny(q, patchSignals) ||
+		strings.HasPrefix(q, "why") || containsAny(q, []string{"kubectl patch", "rebooted", "reboots"}) {
+		return PatchIntent{}, false
+	}
+
+	var intent PatchIntent
+	for _, pattern := range []*regexp.Regexp{upgradeClusterNamePattern, upgradeNameClusterPattern} {
+		if m := pattern.FindStringSubmatch(q); m != nil && !upgradeNameStopWords[m[1]] && !patchNameStopWords[m[1]] {
+			intent.ClusterName = m[1]
+			break
+		}
+	}
+	if m := upgradeProviderPattern.FindStringSubmatch(q); m != nil {
+		intent.ClusterType = ClusterType(m[1])
+	}
+
+	return intent, true
+}
+
+// patchNameStopWords are words around "cluster" in patch requests that are
+// not a cluster name
+var patchNameStopWords = map[string]bool{
+	"of": true, "in": true, "on": true, "all": true, "nodes": true, "every": true,
+}
+
+// PatchClusterNodes patches and reboots or replaces a cluster's nodes one at
+// a time with its registered provider
+func (a *Agent) PatchClusterNodes(ctx context.Context, clusterType ClusterType, clusterName string, opts cluster.PatchOptions) error {
+	return a.clusterMgr.PatchNodes(ctx, clusterType, clusterName, opts)
+}
+
+// generatePatchPlan turns a node patching request into the phases the
+// provider runs. The plan is applied with `clanker k8s patch`, which
+// reports progress node by node.
+func (a *Agent) generatePatchPlan(query string, opts QueryOptions, k8sPlan *K8sPlan) (*K8sPlan, error) {
+	intent, _ := ParsePatchQuery(query)
+	if intent.ClusterName == "" {
+		intent.ClusterName = opts.ClusterName
+	}
+	if intent.ClusterType == "" {
+		intent.ClusterType = opts.ClusterType
+	}
+	if intent.ClusterName != "" {
+		k8sPlan.ClusterName = intent.ClusterName
+	}
+	k8sPlan.ClusterType = intent.ClusterType
+
+	switch intent.ClusterType {
+	case ClusterTypeEKS, ClusterTypeGKE, ClusterTypeAKS, ClusterTypeKubeadm:
+	default:
+		k8sPlan.Summary = "Patch and reboot cluster nodes one at a time"
+		k8sPlan.Warnings = append(k8sPlan.Warnings,
+			"Cluster type unknown: name the provider (eks, gke, aks or kubeadm) in the request or set kubernetes.default_type")
+		return k8sPlan, nil
+	}
+
+	name := intent.ClusterName
+	if name == "" {
+		name = "<cluster>"
+		k8sPlan.Warnings = append(k8sPlan.Warnings, "Cluster name not found in the request")
+	}
+
+	patchPlan := plan.GeneratePatchPlan(plan.PatchOptions{
+		ClusterType: string(intent.ClusterType),
+		ClusterName: name,
+	})
+
+	k8sPlan.Summary = patchPlan.Summary
+	appendBootstrapSteps(k8sPlan, patchPlan.Steps, string(intent.ClusterType), name)
+	k8sPlan.Notes = append(k8sPlan.Notes, patchPlan.Notes...)
+	k8sPlan.Notes = append(k8sPlan.Notes,
+		fmt.Sprintf("Apply with: clanker k8s patch %s %s", intent.ClusterType, name))
+	k8sPlan.Warnings = append(k8sPlan.Warnings,
+		"Every node is drained and rebooted or replaced; workloads with a single replica are briefly unavailable")
+
+	return k8sPlan, nil
+}
@@ -0,0 +1,79 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParsePatchQuery(t *testing.T) {
+	tests := []struct {
+		query       string
+		ok          bool
+		clusterName string
+		clusterType ClusterType
+	}{
+		{query: "patch and reboot all nodes of cluster prod one at a time", ok: true, clusterName: "prod"},
+		{query: "apply security updates to the nodes of the lab kubeadm cluster", ok: true, clusterName: "lab", clusterType: ClusterTypeKubeadm},
+		{query: "rolling reboot of the nodes in aks cluster web-01", ok: true, clusterName: "web-01", clusterType: ClusterTypeAKS},
+		{query: "move the gke cluster shop nodes to the latest node image", ok: true, clusterName: "shop", clusterType: ClusterTypeGKE},
+		{query: "reboot every node in my eks cluster", ok: true, clusterType: ClusterTypeEKS},
+		{query: "why did node ip-10-0-1-5 reboot", ok: false},
+		{query: "kubectl patch node worker-1 to add a label", ok: false},
+		{query: "patch the deployment web", ok: false},
+		{query: "add 2 nodes to the cluster", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParsePatchQuery(tt.query)
+		if ok != tt.ok {
+			t.Errorf("ParsePatchQuery(%q) ok = %v, want %v", tt.query, ok, tt.ok)
+			continue
+		}
+		if ok && (got.ClusterName != tt.clusterName || got.ClusterType != tt.clusterType) {
+			t.Errorf("ParsePatchQuery(%q) = %+v, want name=%q type=%q", tt.query, got, tt.clusterName, tt.clusterType)
+		}
+	}
+}
+
+func TestGeneratePatchPlan(t *testing.T) {
+	a := &Agent{}
+	query := "patch and reboot all nodes of kubeadm cluster lab one at a time"
+	plan, err := a.generatePlan(context.Background(), query, a.analyzeQuery(query), QueryOptions{})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+
+	if plan.ClusterName != "lab" || plan.ClusterType != ClusterTypeKubeadm {
+		t.Errorf("cluster = %s/%s, want kubeadm/lab", plan.ClusterType, plan.ClusterName)
+	}
+	var phases, nodeCommands []string
+	for _, cmd := range plan.Bootstrap {
+		if len(phases) == 0 || phases[len(phases)-1] != cmd.Operation {
+			phases = append(phases, cmd.Operation)
+		}
+		if cmd.Operation == "nodes" {
+			nodeCommands = append(nodeCommands, cmd.Command)
+		}
+	}
+	if strings.Join(phases, ",") != "pre-flight,nodes,verify" {
+		t.Errorf("phases = %v", phases)
+	}
+	want := "kubectl drain <NODE> --ignore-daemonsets --delete-emptydir-data|apt-get -y dist-upgrade|systemctl reboot|kubectl uncordon <NODE>"
+	if strings.Join(nodeCommands, "|") != want {
+		t.Errorf("node commands = %v", nodeCommands)
+	}
+	if !strings.Contains(strings.Join(plan.Notes, "\n"), "Apply with: clanker k8s patch kubeadm lab") {
+		t.Errorf("notes = %v", plan.Notes)
+	}
+
+	// Without a provider the plan only explains what is missing
+	query = "patch and reboot all nodes of cluster prod"
+	plan, err = a.generatePlan(context.Background(), query, a.analyzeQuery(query), QueryOptions{})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+	if len(plan.Bootstrap) != 0 || len(plan.Warnings) == 0 || !strings.Contains(plan.Warnings[0], "Cluster type unknown") {
+		t.Errorf("plan without provider = %+v", plan)
+	}
+}
@@ -0,0 +1,178 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+	"gopkg.in/yaml.v3"
+)
+
+// AppliedPlan records the manifests that were applied to a cluster so they
+// can later be compared against live state
+type AppliedPlan struct {
+	ID        string            `json:"id"`
+	AppliedAt time.Time         `json:"appliedAt"`
+	Source    string            `json:"source"` // deploy, apply
+	Summary   string            `json:"summary,omitempty"`
+	Context   string            `json:"context,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Manifests []AppliedManifest `json:"manifests"`
+}
+
+// AppliedManifest is a single Kubernetes object from an applied plan
+type AppliedManifest struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Content   string `json:"content"`
+}
+
+// NewAppliedPlan splits a multi-document manifest into an AppliedPlan.
+// Objects without a namespace inherit defaultNamespace.
+func NewAppliedPlan(source, summary, kubeContext, defaultNamespace, manifest string) (*AppliedPlan, error) {
+	manifests, err := SplitManifests(manifest, defaultNamespace)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("manifest contains no Kubernetes objects")
+	}
+
+	now := time.Now().UTC()
+	return &AppliedPlan{
+		ID:        fmt.Sprintf("%s-%s", now.Format("20060102-150405"), sanitizeFilename(manifests[0].Name)),
+		AppliedAt: now,
+		Source:    source,
+		Summary:   summary,
+		Context:   kubeContext,
+		Namespace: defaultNamespace,
+		Manifests: manifests,
+	}, nil
+}
+
+// SplitManifests parses a multi-document YAML manifest into individual objects
+func SplitManifests(manifest, defaultNamespace string) ([]AppliedManifest, error) {
+	var out []AppliedManifest
+	for _, doc := range strings.Split(manifest, "\n---") {
+		doc = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(doc), "---"))
+		if doc == "" {
+			continue
+		}
+		var obj struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name      string `yaml:"name"`
+				Namespace string `yaml:"namespace"`
+			} `yaml:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if obj.Kind == "" || obj.Metadata.Name == "" {
+			continue
+		}
+		namespace := obj.Metadata.Namespace
+		if namespace == "" {
+			namespace = defaultNamespace
+		}
+		out = append(out, AppliedManifest{
+			Kind:      obj.Kind,
+			Name:      obj.Metadata.Name,
+			Namespace: namespace,
+			Content:   doc + "\n",
+		})
+	}
+	return out, nil
+}
+
+// CombinedManifest joins the recorded manifests back into one YAML stream
+func (p *AppliedPlan) CombinedManifest() string {
+	docs := make([]string, 0, len(p.Manifests))
+	for _, m := range p.Manifests {
+		docs = append(docs, strings.TrimSpace(m.Content))
+	}
+	return strings.Join(docs, "\n---\n") + "\n"
+}
+
+// appliedPlansDir returns ~/.clanker/plans/applied
+func appliedPlansDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "plans", "applied"), nil
+}
+
+// SaveAppliedPlan writes the record to ~/.clanker/plans/applied/<id>.json
+func SaveAppliedPlan(p *AppliedPlan) (string, error) {
+	dir, err := appliedPlansDir()
+	if err != nil {
+		return "", err
+	}
+	if err := secfile.EnsurePrivateDir(dir); err != nil {
+		return "", fmt.Errorf("failed to create applied plans directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal applied plan: %w", err)
+	}
+
+	path := filepath.Join(dir, secfile.SafeSlug(p.ID)+".json")
+	if err := secfile.WritePrivate(path, data); err != nil {
+		return "", fmt.Errorf("failed to write applied plan: %w", err)
+	}
+	return path, nil
+}
+
+// LoadAppliedPlan reads a previously applied plan by ID
+func LoadAppliedPlan(id string) (*AppliedPlan, error) {
+	dir, err := appliedPlansDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := secfile.ReadPrivate(filepath.Join(dir, secfile.SafeSlug(id)+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no applied plan with id %q", id)
+		}
+		return nil, fmt.Errorf("failed to read applied plan: %w", err)
+	}
+
+	var p AppliedPlan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse applied plan: %w", err)
+	}
+	return &p, nil
+}
+
+// ListAppliedPlans returns the IDs of recorded applied plans, newest first
+func ListAppliedPlans() ([]string, error) {
+	dir, err := appliedPlansDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
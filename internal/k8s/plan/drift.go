@@ -0,0 +1,322 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LiveFetcher returns the live object as JSON. It returns (nil, nil) when the
+// object no longer exists in the cluster.
+type LiveFetcher func(ctx context.Context, kind, name, namespace string) ([]byte, error)
+
+// FieldDrift is a single field whose live value differs from the plan
+type FieldDrift struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Field     string `json:"field"`
+	Expected  string `json:"expected"`
+	Live      string `json:"live"`
+}
+
+// DriftReport summarizes drift for every object in an applied plan
+type DriftReport struct {
+	PlanID    string       `json:"planId"`
+	Checked   int          `json:"checked"`
+	Missing   []string     `json:"missing,omitempty"`
+	Drifted   []FieldDrift `json:"drifted,omitempty"`
+	FetchErrs []string     `json:"fetchErrors,omitempty"`
+}
+
+// HasDrift reports whether any object is missing or diverged
+func (r *DriftReport) HasDrift() bool {
+	return len(r.Missing) > 0 || len(r.Drifted) > 0
+}
+
+// DetectDrift re-fetches every object in the applied plan and compares image
+// tags, replica counts, and container env vars against the recorded manifests.
+func DetectDrift(ctx context.Context, applied *AppliedPlan, fetch LiveFetcher) (*DriftReport, error) {
+	if applied == nil {
+		return nil, fmt.Errorf("applied plan is nil")
+	}
+
+	report := &DriftReport{PlanID: applied.ID}
+	for _, m := range applied.Manifests {
+		ref := manifestRef(m)
+
+		var expected map[string]interface{}
+		if err := yaml.Unmarshal([]byte(m.Content), &expected); err != nil {
+			report.FetchErrs = append(report.FetchErrs, fmt.Sprintf("%s: parse manifest: %v", ref, err))
+			continue
+		}
+
+		raw, err := fetch(ctx, m.Kind, m.Name, m.Namespace)
+		if err != nil {
+			report.FetchErrs = append(report.FetchErrs, fmt.Sprintf("%s: %v", ref, err))
+			continue
+		}
+		report.Checked++
+		if raw == nil {
+			report.Missing = append(report.Missing, ref)
+			continue
+		}
+
+		var live map[string]interface{}
+		if err := json.Unmarshal(raw, &live); err != nil {
+			report.FetchErrs = append(report.FetchErrs, fmt.Sprintf("%s: parse live object: %v", ref, err))
+			continue
+		}
+
+		for _, d := range compareObjects(expected, live) {
+			d.Kind, d.Name, d.Namespace = m.Kind, m.Name, m.Namespace
+			report.Drifted = append(report.Drifted, d)
+		}
+	}
+	return report, nil
+}
+
+// UpdateFromLive rewrites the recorded manifests so drifted fields take their
+// live values, turning the current cluster state into the new baseline.
+func UpdateFromLive(applied *AppliedPlan, report *DriftReport) error {
+	byObject := map[string][]FieldDrift{}
+	for _, d := range report.Drifted {
+		key := d.Kind + "/" + d.Namespace + "/" + d.Name
+		byObject[key] = append(byObject[key], d)
+	}
+
+	for i, m := range applied.Manifests {
+		drifts := byObject[m.Kind+"/"+m.Namespace+"/"+m.Name]
+		if len(drifts) == 0 {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(m.Content), &obj); err != nil {
+			return fmt.Errorf("parse manifest %s: %w", manifestRef(m), err)
+		}
+		for _, d := range drifts {
+			applyLiveField(obj, d)
+		}
+		out, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("marshal manifest %s: %w", manifestRef(m), err)
+		}
+		applied.Manifests[i].Content = string(out)
+	}
+	return nil
+}
+
+func manifestRef(m AppliedManifest) string {
+	if m.Namespace == "" {
+		return fmt.Sprintf("%s/%s", m.Kind, m.Name)
+	}
+	return fmt.Sprintf("%s/%s (ns %s)", m.Kind, m.Name, m.Namespace)
+}
+
+// compareObjects diffs the fields a plan owns: spec.replicas and, for each
+// container declared in the plan, its image and env vars.
+func compareObjects(expected, live map[string]interface{}) []FieldDrift {
+	var drifts []FieldDrift
+
+	if want, ok := nestedValue(expected, "spec", "replicas"); ok {
+		got, _ := nestedValue(live, "spec", "replicas")
+		if scalarString(want) != scalarString(got) {
+			drifts = append(drifts, FieldDrift{Field: "spec.replicas", Expected: scalarString(want), Live: scalarString(got)})
+		}
+	}
+
+	liveContainers := containersByName(live)
+	for _, container := range containerList(expected) {
+		name := scalarString(container["name"])
+		got, ok := liveContainers[name]
+		if !ok {
+			drifts = append(drifts, FieldDrift{Field: fmt.Sprintf("containers[%s]", name), Expected: "present", Live: "missing"})
+			continue
+		}
+		if want := scalarString(container["image"]); want != "" && want != scalarString(got["image"]) {
+			drifts = append(drifts, FieldDrift{Field: fmt.Sprintf("containers[%s].image", name), Expected: want, Live: scalarString(got["image"])})
+		}
+
+		wantEnv := envMap(container)
+		gotEnv := envMap(got)
+		keys := make([]string, 0, len(wantEnv)+len(gotEnv))
+		for k := range wantEnv {
+			keys = append(keys, k)
+		}
+		for k := range gotEnv {
+			if _, ok := wantEnv[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			want, inPlan := wantEnv[k]
+			have, inLive := gotEnv[k]
+			switch {
+			case inPlan && !inLive:
+				drifts = append(drifts, FieldDrift{Field: fmt.Sprintf("containers[%s].env[%s]", name, k), Expected: want, Live: "<unset>"})
+			case !inPlan && inLive:
+				drifts = append(drifts, FieldDrift{Field: fmt.Sprintf("containers[%s].env[%s]", name, k), Expected: "<unset>", Live: have})
+			case want != have:
+				drifts = append(drifts, FieldDrift{Field: fmt.Sprintf("containers[%s].env[%s]", name, k), Expected: want, Live: have})
+			}
+		}
+	}
+	return drifts
+}
+
+// podSpec locates the pod spec for Pods, workload controllers, and CronJobs
+func podSpec(obj map[string]interface{}) map[string]interface{} {
+	for _, path := range [][]string{
+		{"spec", "jobTemplate", "spec", "template", "spec"},
+		{"spec", "template", "spec"},
+		{"spec"},
+	} {
+		if v, ok := nestedValue(obj, path...); ok {
+			if m, ok := v.(map[string]interface{}); ok {
+				if _, has := m["containers"]; has {
+					return m
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func containerList(obj map[string]interface{}) []map[string]interface{} {
+	spec := podSpec(obj)
+	if spec == nil {
+		return nil
+	}
+	items, _ := spec["containers"].([]interface{})
+	out := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func containersByName(obj map[string]interface{}) map[string]map[string]interface{} {
+	out := map[string]map[string]interface{}{}
+	for _, c := range containerList(obj) {
+		out[scalarString(c["name"])] = c
+	}
+	return out
+}
+
+// envMap returns literal env values; valueFrom references are shown as such
+func envMap(container map[string]interface{}) map[string]string {
+	out := map[string]string{}
+	items, _ := container["env"].([]interface{})
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name := scalarString(m["name"])
+		if name == "" {
+			continue
+		}
+		if _, ok := m["valueFrom"]; ok {
+			out[name] = "<valueFrom>"
+			continue
+		}
+		out[name] = scalarString(m["value"])
+	}
+	return out
+}
+
+func nestedValue(obj map[string]interface{}, path ...string) (interface{}, bool) {
+	var current interface{} = obj
+	for _, key := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func scalarString(v interface{}) string {
+	switch typed := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case float64:
+		if typed == float64(int64(typed)) {
+			return fmt.Sprintf("%d", int64(typed))
+		}
+		return fmt.Sprintf("%g", typed)
+	default:
+		return fmt.Sprint(typed)
+	}
+}
+
+// applyLiveField writes a drifted live value back into a manifest object
+func applyLiveField(obj map[string]interface{}, d FieldDrift) {
+	if d.Field == "spec.replicas" {
+		if spec, ok := obj["spec"].(map[string]interface{}); ok {
+			var replicas int
+			if _, err := fmt.Sscanf(d.Live, "%d", &replicas); err == nil {
+				spec["replicas"] = replicas
+			}
+		}
+		return
+	}
+
+	if !strings.HasPrefix(d.Field, "containers[") {
+		return
+	}
+	rest := strings.TrimPrefix(d.Field, "containers[")
+	end := strings.Index(rest, "]")
+	if end < 0 {
+		return
+	}
+	name, attr := rest[:end], strings.TrimPrefix(rest[end+1:], ".")
+
+	for _, c := range containerList(obj) {
+		if scalarString(c["name"]) != name {
+			continue
+		}
+		switch {
+		case attr == "image":
+			c["image"] = d.Live
+		case strings.HasPrefix(attr, "env[") && strings.HasSuffix(attr, "]"):
+			setEnv(c, strings.TrimSuffix(strings.TrimPrefix(attr, "env["), "]"), d.Live)
+		}
+	}
+}
+
+func setEnv(container map[string]interface{}, key, value string) {
+	items, _ := container["env"].([]interface{})
+	out := make([]interface{}, 0, len(items)+1)
+	found := false
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if ok && scalarString(m["name"]) == key {
+			found = true
+			if value == "<unset>" {
+				continue
+			}
+			if value != "<valueFrom>" {
+				m["value"] = value
+			}
+		}
+		out = append(out, item)
+	}
+	if !found && value != "<unset>" && value != "<valueFrom>" {
+		out = append(out, map[string]interface{}{"name": key, "value": value})
+	}
+	container["env"] = out
+}
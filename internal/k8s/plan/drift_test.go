@@ -0,0 +1,153 @@
+package plan
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const driftTestManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: api
+        image: example/api:1.0
+        env:
+        - name: LOG_LEVEL
+          value: info
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+  namespace: web
+spec:
+  ports:
+  - port: 80
+`
+
+func TestNewAppliedPlanSplitsManifests(t *testing.T) {
+	applied, err := NewAppliedPlan("apply", "", "prod", "default", driftTestManifest)
+	if err != nil {
+		t.Fatalf("NewAppliedPlan: %v", err)
+	}
+	if len(applied.Manifests) != 2 {
+		t.Fatalf("expected 2 manifests, got %d", len(applied.Manifests))
+	}
+	if applied.Manifests[0].Namespace != "default" || applied.Manifests[1].Namespace != "web" {
+		t.Errorf("unexpected namespaces: %+v", applied.Manifests)
+	}
+	if !strings.HasSuffix(applied.ID, "-api") {
+		t.Errorf("expected id to end with object name, got %q", applied.ID)
+	}
+	if strings.Count(applied.CombinedManifest(), "---") != 1 {
+		t.Errorf("combined manifest should rejoin documents, got:\n%s", applied.CombinedManifest())
+	}
+}
+
+func TestDetectDrift(t *testing.T) {
+	applied, err := NewAppliedPlan("apply", "", "", "default", driftTestManifest)
+	if err != nil {
+		t.Fatalf("NewAppliedPlan: %v", err)
+	}
+
+	liveDeployment := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": 5,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":  "api",
+							"image": "example/api:1.1",
+							"env": []interface{}{
+								map[string]interface{}{"name": "LOG_LEVEL", "value": "debug"},
+								map[string]interface{}{"name": "EXTRA", "value": "1"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	fetch := func(ctx context.Context, kind, name, namespace string) ([]byte, error) {
+		if kind == "Service" {
+			return nil, nil
+		}
+		return json.Marshal(liveDeployment)
+	}
+
+	report, err := DetectDrift(context.Background(), applied, fetch)
+	if err != nil {
+		t.Fatalf("DetectDrift: %v", err)
+	}
+	if report.Checked != 2 || len(report.Missing) != 1 {
+		t.Fatalf("expected 2 checked and 1 missing, got %+v", report)
+	}
+
+	fields := map[string]FieldDrift{}
+	for _, d := range report.Drifted {
+		fields[d.Field] = d
+	}
+	for field, live := range map[string]string{
+		"spec.replicas":                  "5",
+		"containers[api].image":          "example/api:1.1",
+		"containers[api].env[LOG_LEVEL]": "debug",
+		"containers[api].env[EXTRA]":     "1",
+	} {
+		d, ok := fields[field]
+		if !ok {
+			t.Errorf("expected drift on %s, got %+v", field, report.Drifted)
+			continue
+		}
+		if d.Live != live {
+			t.Errorf("%s: live = %q, want %q", field, d.Live, live)
+		}
+	}
+
+	if err := UpdateFromLive(applied, report); err != nil {
+		t.Fatalf("UpdateFromLive: %v", err)
+	}
+	again, err := DetectDrift(context.Background(), applied, fetch)
+	if err != nil {
+		t.Fatalf("DetectDrift after update: %v", err)
+	}
+	if len(again.Drifted) != 0 {
+		t.Errorf("expected no field drift after update, got %+v", again.Drifted)
+	}
+}
+
+func TestSaveAndLoadAppliedPlan(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	applied, err := NewAppliedPlan("deploy", "Deploy api", "prod", "default", driftTestManifest)
+	if err != nil {
+		t.Fatalf("NewAppliedPlan: %v", err)
+	}
+	if _, err := SaveAppliedPlan(applied); err != nil {
+		t.Fatalf("SaveAppliedPlan: %v", err)
+	}
+
+	loaded, err := LoadAppliedPlan(applied.ID)
+	if err != nil {
+		t.Fatalf("LoadAppliedPlan: %v", err)
+	}
+	if loaded.Summary != "Deploy api" || len(loaded.Manifests) != 2 {
+		t.Errorf("unexpected loaded plan: %+v", loaded)
+	}
+
+	ids, err := ListAppliedPlans()
+	if err != nil || len(ids) != 1 || ids[0] != applied.ID {
+		t.Errorf("ListAppliedPlans = %v, %v", ids, err)
+	}
+
+	if _, err := LoadAppliedPlan("missing"); err == nil {
+		t.Error("expected error for unknown plan id")
+	}
+}
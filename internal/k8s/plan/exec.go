@@ -7,10 +7,15 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/dryrun"
+	"github.com/bgdnvk/clanker/internal/planrun"
 )
 
 var placeholderRe = regexp.MustCompile(`<([A-Z0-9_]+)>`)
@@ -23,6 +28,9 @@ func Execute(ctx context.Context, plan *K8sPlan, opts ExecOptions, w io.Writer)
 	if w == nil {
 		w = os.Stdout
 	}
+	if dryrun.Enabled() {
+		opts.DryRun = true
+	}
 
 	progress := NewProgressWriter(w, len(plan.Steps), opts.Debug)
 	bindings := make(map[string]string)
@@ -36,10 +44,19 @@ func Execute(ctx context.Context, plan *K8sPlan, opts ExecOptions, w io.Writer)
 	bindings["REGION"] = plan.Region
 	bindings["PROFILE"] = plan.Profile
 
-	for _, step := range plan.Steps {
+	for i, step := range plan.Steps {
+		if planrun.Interrupted(ctx) {
+			result.Success = false
+			return result, planrun.Interrupt(i, len(plan.Steps))
+		}
 		progress.StartStep(step)
 
 		stepResult, err := executeStep(ctx, step, opts, bindings, progress)
+		if err != nil && planrun.Interrupted(ctx) {
+			result.Success = false
+			progress.LogError(fmt.Sprintf("step %s interrupted", step.ID))
+			return result, planrun.Interrupt(i, len(plan.Steps))
+		}
 		if err != nil {
 			result.Success = false
 			result.Errors = append(result.Errors, fmt.Sprintf("Step %s failed: %v", step.ID, err))
@@ -53,8 +70,8 @@ func Execute(ctx context.Context, plan *K8sPlan, opts ExecOptions, w io.Writer)
 			progress.LogBinding(k, v)
 		}
 
-		// Handle wait conditions
-		if step.WaitFor != nil {
+		// Handle wait conditions; a dry run created nothing to wait for
+		if step.WaitFor != nil && !opts.DryRun {
 			if err := executeWait(ctx, step.WaitFor, opts, bindings, progress); err != nil {
 				result.Success = false
 				result.Errors = append(result.Errors, fmt.Sprintf("Wait for %s failed: %v", step.WaitFor.Type, err))
@@ -99,17 +116,23 @@ func executeStep(ctx context.Context, step Step, opts ExecOptions, bindings map[
 		args = append(args, "--profile", opts.Profile, "--region", opts.Region)
 	}
 
-	cmdStr := formatCommandForLog(step.Command, args)
-	progress.LogCommand(step.Command, cmdStr)
-
-	if opts.DryRun {
-		progress.LogNote("dry-run: skipping execution")
+	// Lookups still run on a dry run, so later steps get real values
+	if opts.DryRun && dryrun.Mutates(step.Command, args) {
+		dryrun.Print(progress.w, step.Command, args)
 		result.Success = true
 		return result, nil
 	}
 
+	cmdStr := formatCommandForLog(step.Command, args)
+	progress.LogCommand(step.Command, cmdStr)
+
 	// Execute command
-	output, err := runCommandStreaming(ctx, step.Command, args, progress, step.Command)
+	stepCtx, done, err := planrun.Step(ctx, step.Timeout)
+	if err != nil {
+		return result, err
+	}
+	output, err := runCommandStreaming(stepCtx, step.Command, args, step.Manifest, progress, step.Command)
+	err = done(err)
 	result.Output = output
 
 	if err != nil {
@@ -136,6 +159,9 @@ func executeSSHStep(ctx context.Context, step Step, opts ExecOptions, bindings m
 	cfg := step.SSHConfig
 	host := applyBindingsToString(cfg.Host, bindings)
 	user := cfg.User
+	if user == "" {
+		user = opts.SSHUser
+	}
 	if user == "" {
 		user = "ubuntu"
 	}
@@ -144,11 +170,21 @@ func executeSSHStep(ctx context.Context, step Step, opts ExecOptions, bindings m
 		keyPath = opts.SSHKeyPath
 	}
 	keyPath = expandPath(keyPath)
+	jumpHost := cfg.JumpHost
+	if jumpHost == "" {
+		jumpHost = opts.SSHJumpHost
+	}
+
+	if opts.DryRun {
+		dryrun.PrintScript(progress.w, user+"@"+host, applyBindingsToString(cfg.Script, bindings))
+		result.Success = true
+		return result, nil
+	}
 
 	progress.LogSSH(host, user)
 
 	// Wait for SSH to be available
-	if err := waitForSSH(ctx, host, user, keyPath, progress); err != nil {
+	if err := waitForSSH(ctx, host, user, keyPath, jumpHost, progress); err != nil {
 		return result, fmt.Errorf("SSH connection failed: %w", err)
 	}
 
@@ -158,13 +194,12 @@ func executeSSHStep(ctx context.Context, step Step, opts ExecOptions, bindings m
 	script := applyBindingsToString(cfg.Script, bindings)
 	progress.LogSSHCommand(cfg.ScriptName)
 
-	if opts.DryRun {
-		progress.LogNote("dry-run: skipping SSH execution")
-		result.Success = true
-		return result, nil
+	stepCtx, done, err := planrun.Step(ctx, step.Timeout)
+	if err != nil {
+		return result, err
 	}
-
-	output, err := runSSHCommand(ctx, host, user, keyPath, script, progress)
+	output, err := runSSHCommand(stepCtx, host, user, keyPath, jumpHost, script, progress)
+	err = done(err)
 	result.Output = output
 
 	if err != nil {
@@ -298,12 +333,16 @@ func checkPodsReady(ctx context.Context, selector string, opts ExecOptions) (boo
 	return true, fmt.Sprintf("%d pods running", len(phases)), nil
 }
 
-func runCommandStreaming(ctx context.Context, command string, args []string, progress *ProgressWriter, prefix string) (string, error) {
+func runCommandStreaming(ctx context.Context, command string, args []string, stdin string, progress *ProgressWriter, prefix string) (string, error) {
 	cmd := exec.CommandContext(ctx, command, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
 	stdout, _ := cmd.StdoutPipe()
 	stderr, _ := cmd.StderrPipe()
 
 	if err := cmd.Start(); err != nil {
+		audit.RecordStep(command, args, err)
 		return "", err
 	}
 
@@ -335,38 +374,61 @@ func runCommandStreaming(ctx context.Context, command string, args []string, pro
 	go drain(stderr)
 	wg.Wait()
 
-	if err := cmd.Wait(); err != nil {
+	err := cmd.Wait()
+	audit.RecordStep(command, args, err)
+	if err != nil {
 		return output.String(), err
 	}
 
 	return output.String(), nil
 }
 
-func runSSHCommand(ctx context.Context, host, user, keyPath, script string, progress *ProgressWriter) (string, error) {
+// sshArgs builds the common ssh options. Connections are multiplexed
+// through a ControlMaster socket kept open between steps, so the scripts
+// run on one node share a single connection, and jumpHost routes them
+// through a bastion.
+func sshArgs(keyPath, jumpHost string, options ...string) []string {
 	args := []string{
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "UserKnownHostsFile=/dev/null",
-		"-o", "ConnectTimeout=10",
-		"-i", keyPath,
+		"-o", "ControlMaster=auto",
+		"-o", "ControlPath=" + filepath.Join(os.TempDir(), "clanker-ssh-%C"),
+		"-o", "ControlPersist=2m",
+	}
+	for _, opt := range options {
+		args = append(args, "-o", opt)
+	}
+	if jumpHost != "" {
+		args = append(args, "-J", jumpHost)
+	}
+	return append(args, "-i", keyPath)
+}
+
+// scpArgs builds scp arguments that match sshArgs
+func scpArgs(keyPath, jumpHost string, paths ...string) []string {
+	args := []string{"-o", "StrictHostKeyChecking=no", "-i", keyPath}
+	if jumpHost != "" {
+		args = append(args, "-J", jumpHost)
+	}
+	return append(args, paths...)
+}
+
+func runSSHCommand(ctx context.Context, host, user, keyPath, jumpHost, script string, progress *ProgressWriter) (string, error) {
+	args := append(sshArgs(keyPath, jumpHost, "ConnectTimeout=10"),
 		fmt.Sprintf("%s@%s", user, host),
 		script,
-	}
+	)
 
-	return runCommandStreaming(ctx, "ssh", args, progress, "ssh")
+	return runCommandStreaming(ctx, "ssh", args, "", progress, "ssh")
 }
 
-func waitForSSH(ctx context.Context, host, user, keyPath string, progress *ProgressWriter) error {
+func waitForSSH(ctx context.Context, host, user, keyPath, jumpHost string, progress *ProgressWriter) error {
 	maxAttempts := 30
 	for i := 0; i < maxAttempts; i++ {
-		args := []string{
-			"-o", "StrictHostKeyChecking=no",
-			"-o", "UserKnownHostsFile=/dev/null",
-			"-o", "ConnectTimeout=5",
-			"-o", "BatchMode=yes",
-			"-i", keyPath,
+		args := append(sshArgs(keyPath, jumpHost, "ConnectTimeout=5", "BatchMode=yes"),
 			fmt.Sprintf("%s@%s", user, host),
 			"echo ok",
-		}
+		)
 
 		cmd := exec.CommandContext(ctx, "ssh", args...)
 		if err := cmd.Run(); err == nil {
@@ -447,3 +447,33 @@ func TestPlaceholderRegex(t *testing.T) {
 		}
 	}
 }
+
+func TestSSHArgs(t *testing.T) {
+	args := strings.Join(sshArgs("/keys/id", "admin@bastion:2222", "ConnectTimeout=5"), " ")
+	for _, want := range []string{"-o ControlMaster=auto", "-o ControlPersist=2m", "-o ConnectTimeout=5", "-J admin@bastion:2222 -i /keys/id"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("sshArgs missing %q: %s", want, args)
+		}
+	}
+	if args := strings.Join(sshArgs("/keys/id", ""), " "); strings.Contains(args, "-J") {
+		t.Errorf("sshArgs without jump host: %s", args)
+	}
+}
+
+func TestExecuteDryRunSSHUser(t *testing.T) {
+	plan := &K8sPlan{
+		Steps: []Step{{
+			ID:        "bootstrap",
+			Command:   "ssh",
+			SSHConfig: &SSHStepConfig{Host: "10.0.0.2", Script: "kubeadm version"},
+		}},
+	}
+
+	var output strings.Builder
+	if _, err := Execute(context.Background(), plan, ExecOptions{DryRun: true, SSHUser: "admin"}, &output); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), "admin@10.0.0.2") {
+		t.Errorf("dry run output = %s", output.String())
+	}
+}
@@ -24,6 +24,13 @@ type GKECreateOptions struct {
 	NodeType          string
 	KubernetesVersion string
 	Preemptible       bool
+	// Autopilot creates an Autopilot cluster; node count and type are unused
+	Autopilot      bool
+	ReleaseChannel string
+	// AutoProvisioningMaxCPU and AutoProvisioningMaxMemoryGB turn on node
+	// auto-provisioning when both are set
+	AutoProvisioningMaxCPU      int
+	AutoProvisioningMaxMemoryGB int
 }
 
 // KubeadmCreateOptions holds options for kubeadm cluster creation
@@ -31,12 +38,15 @@ type KubeadmCreateOptions struct {
 	ClusterName       string
 	Region            string
 	Profile           string
+	ControlPlaneCount int
 	WorkerCount       int
 	NodeType          string
 	ControlPlaneType  string
 	KubernetesVersion string
 	KeyPairName       string
 	SSHKeyPath        string
+	SSHUser           string // node login user; default ubuntu
+	JumpHost          string // bastion for nodes in private subnets, [user@]host[:port]
 	CNI               string // calico, flannel
 }
 
@@ -47,7 +57,12 @@ type DeployOptions struct {
 	Port      int
 	Replicas  int
 	Namespace string
-	Type      string // deployment, statefulset
+	Type      string // deployment, statefulset, job or cronjob
+	Schedule  string // cron schedule of a cronjob
+	Manifest  string // generated YAML; applied instead of kubectl create/expose when set
+
+	PullSecret       string // imagePullSecret the manifests reference
+	PullSecretConfig string // docker config to create PullSecret from; empty when it already exists
 }
 
 // DeleteOptions holds options for cluster deletion
@@ -58,6 +73,61 @@ type DeleteOptions struct {
 	Profile     string
 }
 
+// UpgradeOptions holds options for a cluster version upgrade
+type UpgradeOptions struct {
+	ClusterType    string
+	ClusterName    string
+	Region         string
+	Profile        string // AWS profile, GCP project or Azure resource group
+	CurrentVersion string // shown when known
+	TargetVersion  string
+	// Backup is a Velero backup taken before anything changes; nil when
+	// Velero is not installed
+	Backup *UpgradeBackup
+}
+
+// UpgradeBackup is the Velero Backup object a pre-upgrade backup applies
+type UpgradeBackup struct {
+	Name      string
+	Namespace string // Velero's namespace
+	Manifest  string
+}
+
+// PatchOptions holds options for patching node operating systems
+type PatchOptions struct {
+	ClusterType string
+	ClusterName string
+	Region      string
+	Profile     string // AWS profile, GCP project or Azure resource group
+}
+
+// AddonOptions holds options for installing or upgrading an EKS add-on
+type AddonOptions struct {
+	ClusterName       string
+	Region            string
+	Profile           string
+	Addon             string // EKS add-on name
+	Version           string // default (install) or newest (upgrade) version when empty
+	KubernetesVersion string // looked up by the plan when empty
+	Upgrade           bool
+
+	// IRSA role for the add-on's service account; no role steps are
+	// generated when PolicyARN is empty
+	Namespace      string
+	ServiceAccount string
+	PolicyARN      string
+}
+
+// Upgrade plan phases, in the order they run. Patch plans use the
+// pre-flight, nodes and verify phases.
+const (
+	UpgradePhasePreflight    = "pre-flight"
+	UpgradePhaseControlPlane = "control plane"
+	UpgradePhaseAddons       = "add-ons"
+	UpgradePhaseNodes        = "nodes"
+	UpgradePhaseVerify       = "verify"
+)
+
 // GenerateEKSCreatePlan generates a plan for creating an EKS cluster
 func GenerateEKSCreatePlan(opts EKSCreateOptions) *K8sPlan {
 	plan := &K8sPlan{
@@ -189,20 +259,49 @@ func GenerateGKECreatePlan(opts GKECreateOptions) *K8sPlan {
 	}
 
 	// Build gcloud create command args
-	createArgs := []string{
-		"container", "clusters", "create", opts.ClusterName,
-		"--project", opts.Project,
-		"--region", opts.Region,
-		"--num-nodes", fmt.Sprintf("%d", opts.NodeCount),
-		"--machine-type", opts.NodeType,
+	var createArgs []string
+	if opts.Autopilot {
+		plan.Summary = fmt.Sprintf("Create GKE Autopilot cluster '%s'", opts.ClusterName)
+		// Autopilot sizes nodes to the pods, so the node notes do not apply
+		plan.Notes = []string{
+			"Cluster creation typically takes 5-10 minutes",
+			"Autopilot provisions and bills nodes for the pods scheduled on them",
+			fmt.Sprintf("Project: %s", opts.Project),
+			fmt.Sprintf("Region: %s", opts.Region),
+		}
+		createArgs = []string{
+			"container", "clusters", "create-auto", opts.ClusterName,
+			"--project", opts.Project,
+			"--region", opts.Region,
+		}
+	} else {
+		createArgs = []string{
+			"container", "clusters", "create", opts.ClusterName,
+			"--project", opts.Project,
+			"--region", opts.Region,
+			"--num-nodes", fmt.Sprintf("%d", opts.NodeCount),
+			"--machine-type", opts.NodeType,
+		}
+		if opts.Preemptible {
+			createArgs = append(createArgs, "--preemptible")
+		}
+		if opts.AutoProvisioningMaxCPU > 0 && opts.AutoProvisioningMaxMemoryGB > 0 {
+			createArgs = append(createArgs,
+				"--enable-autoprovisioning",
+				"--max-cpu", fmt.Sprintf("%d", opts.AutoProvisioningMaxCPU),
+				"--max-memory", fmt.Sprintf("%d", opts.AutoProvisioningMaxMemoryGB))
+			plan.Notes = append(plan.Notes, fmt.Sprintf("Node auto-provisioning adds node pools up to %d vCPUs and %d GB of memory in total",
+				opts.AutoProvisioningMaxCPU, opts.AutoProvisioningMaxMemoryGB))
+		}
 	}
 
 	if opts.KubernetesVersion != "" {
 		createArgs = append(createArgs, "--cluster-version", opts.KubernetesVersion)
 	}
 
-	if opts.Preemptible {
-		createArgs = append(createArgs, "--preemptible")
+	if opts.ReleaseChannel != "" {
+		createArgs = append(createArgs, "--release-channel", opts.ReleaseChannel)
+		plan.Notes = append(plan.Notes, fmt.Sprintf("Release channel: %s (GKE auto-upgrades the cluster in its maintenance windows)", opts.ReleaseChannel))
 	}
 
 	// Step 1: Create GKE cluster
@@ -289,6 +388,15 @@ func GenerateGKECreatePlan(opts GKECreateOptions) *K8sPlan {
 
 // GenerateKubeadmCreatePlan generates a plan for creating a kubeadm cluster
 func GenerateKubeadmCreatePlan(opts KubeadmCreateOptions) *K8sPlan {
+	controlPlanes := opts.ControlPlaneCount
+	if controlPlanes <= 0 {
+		controlPlanes = 1
+	}
+	sshUser := opts.SSHUser
+	if sshUser == "" {
+		sshUser = "ubuntu"
+	}
+
 	plan := &K8sPlan{
 		Version:     CurrentPlanVersion,
 		CreatedAt:   time.Now(),
@@ -297,17 +405,23 @@ func GenerateKubeadmCreatePlan(opts KubeadmCreateOptions) *K8sPlan {
 		ClusterName: opts.ClusterName,
 		Region:      opts.Region,
 		Profile:     opts.Profile,
-		Summary:     fmt.Sprintf("Create kubeadm cluster '%s' with 1 control plane and %d workers", opts.ClusterName, opts.WorkerCount),
+		Summary:     fmt.Sprintf("Create kubeadm cluster '%s' with %d control plane(s) and %d workers", opts.ClusterName, controlPlanes, opts.WorkerCount),
 		Steps:       []Step{},
 		Notes: []string{
 			"Cluster creation typically takes 10-15 minutes",
 			"EC2 instances will be provisioned for control plane and workers",
-			fmt.Sprintf("Control plane: 1 x %s", opts.ControlPlaneType),
+			fmt.Sprintf("Control plane: %d x %s", controlPlanes, opts.ControlPlaneType),
 			fmt.Sprintf("Workers: %d x %s", opts.WorkerCount, opts.NodeType),
 			"Calico CNI will be installed for pod networking",
 		},
 	}
 
+	if controlPlanes > 1 {
+		plan.Notes = append(plan.Notes,
+			fmt.Sprintf("HA: an HAProxy instance (%s-lb) load-balances the API servers and is the --control-plane-endpoint", opts.ClusterName),
+			fmt.Sprintf("HA: %d more control plane nodes join with kubeadm join --control-plane before the workers", controlPlanes-1))
+	}
+
 	cni := opts.CNI
 	if cni == "" {
 		cni = "calico"
@@ -373,8 +487,9 @@ func GenerateKubeadmCreatePlan(opts KubeadmCreateOptions) *K8sPlan {
 		Command:     "ssh",
 		SSHConfig: &SSHStepConfig{
 			Host:       "<CONTROL_PLANE_IP>",
-			User:       "ubuntu",
+			User:       sshUser,
 			KeyPath:    opts.SSHKeyPath,
+			JumpHost:   opts.JumpHost,
 			ScriptName: "bootstrap-k8s-node.sh",
 			Script:     bootstrapNodeScript(opts.KubernetesVersion),
 		},
@@ -388,8 +503,9 @@ func GenerateKubeadmCreatePlan(opts KubeadmCreateOptions) *K8sPlan {
 		Command:     "ssh",
 		SSHConfig: &SSHStepConfig{
 			Host:       "<CONTROL_PLANE_IP>",
-			User:       "ubuntu",
+			User:       sshUser,
 			KeyPath:    opts.SSHKeyPath,
+			JumpHost:   opts.JumpHost,
 			ScriptName: "kubeadm-init.sh",
 			Script:     kubeadmInitScript(opts.KubernetesVersion),
 		},
@@ -407,8 +523,9 @@ func GenerateKubeadmCreatePlan(opts KubeadmCreateOptions) *K8sPlan {
 		Command:     "ssh",
 		SSHConfig: &SSHStepConfig{
 			Host:       "<CONTROL_PLANE_IP>",
-			User:       "ubuntu",
+			User:       sshUser,
 			KeyPath:    opts.SSHKeyPath,
+			JumpHost:   opts.JumpHost,
 			ScriptName: fmt.Sprintf("install-%s.sh", cni),
 			Script:     installCNIScript(cni),
 		},
@@ -452,8 +569,9 @@ func GenerateKubeadmCreatePlan(opts KubeadmCreateOptions) *K8sPlan {
 			Command:     "ssh",
 			SSHConfig: &SSHStepConfig{
 				Host:       fmt.Sprintf("<WORKER_%d_IP>", i),
-				User:       "ubuntu",
+				User:       sshUser,
 				KeyPath:    opts.SSHKeyPath,
+				JumpHost:   opts.JumpHost,
 				ScriptName: "bootstrap-k8s-node.sh",
 				Script:     bootstrapNodeScript(opts.KubernetesVersion),
 			},
@@ -466,8 +584,9 @@ func GenerateKubeadmCreatePlan(opts KubeadmCreateOptions) *K8sPlan {
 			Command:     "ssh",
 			SSHConfig: &SSHStepConfig{
 				Host:       fmt.Sprintf("<WORKER_%d_IP>", i),
-				User:       "ubuntu",
+				User:       sshUser,
 				KeyPath:    opts.SSHKeyPath,
+				JumpHost:   opts.JumpHost,
 				ScriptName: "kubeadm-join.sh",
 				Script:     kubeadmJoinScript(),
 			},
@@ -481,8 +600,9 @@ func GenerateKubeadmCreatePlan(opts KubeadmCreateOptions) *K8sPlan {
 		Command:     "ssh",
 		SSHConfig: &SSHStepConfig{
 			Host:       "<CONTROL_PLANE_IP>",
-			User:       "ubuntu",
+			User:       sshUser,
 			KeyPath:    opts.SSHKeyPath,
+			JumpHost:   opts.JumpHost,
 			ScriptName: "check-nodes.sh",
 			Script:     "kubectl get nodes",
 		},
@@ -499,12 +619,10 @@ func GenerateKubeadmCreatePlan(opts KubeadmCreateOptions) *K8sPlan {
 		ID:          "get-kubeconfig",
 		Description: "Retrieve kubeconfig from control plane",
 		Command:     "scp",
-		Args: []string{
-			"-o", "StrictHostKeyChecking=no",
-			"-i", opts.SSHKeyPath,
-			"ubuntu@<CONTROL_PLANE_IP>:.kube/config",
+		Args: scpArgs(opts.SSHKeyPath, opts.JumpHost,
+			sshUser+"@<CONTROL_PLANE_IP>:.kube/config",
 			fmt.Sprintf("~/.kube/config-%s", opts.ClusterName),
-		},
+		),
 		ConfigChange: &ConfigChange{
 			File:        fmt.Sprintf("~/.kube/config-%s", opts.ClusterName),
 			Description: "Saving kubeconfig for cluster access",
@@ -541,33 +659,68 @@ func GenerateDeployPlan(opts DeployOptions) *K8sPlan {
 		},
 	}
 
-	// Step 1: Create deployment
-	plan.Steps = append(plan.Steps, Step{
-		ID:          "create-deployment",
-		Description: fmt.Sprintf("Create deployment %s", opts.Name),
-		Command:     "kubectl",
-		Args: []string{
-			"create", "deployment", opts.Name,
-			"--image", opts.Image,
-			"--replicas", fmt.Sprintf("%d", opts.Replicas),
-			"-n", opts.Namespace,
-		},
-	})
+	// Give the pods credentials for a private registry first
+	if opts.PullSecret != "" && opts.PullSecretConfig != "" {
+		plan.Steps = append(plan.Steps, Step{
+			ID:          "create-pull-secret",
+			Description: fmt.Sprintf("Create imagePullSecret %s from %s", opts.PullSecret, opts.PullSecretConfig),
+			Command:     "kubectl",
+			Args: []string{
+				"create", "secret", "generic", opts.PullSecret,
+				"--type", "kubernetes.io/dockerconfigjson",
+				"--from-file", ".dockerconfigjson=" + opts.PullSecretConfig,
+				"-n", opts.Namespace,
+			},
+			Reason: "the image is in a private registry",
+		})
+	}
 
-	// Step 2: Expose service
-	plan.Steps = append(plan.Steps, Step{
-		ID:          "create-service",
-		Description: fmt.Sprintf("Expose deployment %s as LoadBalancer", opts.Name),
-		Command:     "kubectl",
-		Args: []string{
-			"expose", "deployment", opts.Name,
-			"--port", fmt.Sprintf("%d", opts.Port),
-			"--type", "LoadBalancer",
-			"-n", opts.Namespace,
-		},
-	})
+	if opts.Manifest != "" {
+		// Step 1: Apply the generated manifests
+		plan.Steps = append(plan.Steps, Step{
+			ID:          "apply-manifests",
+			Description: fmt.Sprintf("Apply generated manifests for %s", opts.Name),
+			Command:     "kubectl",
+			Args:        []string{"apply", "-n", opts.Namespace, "-f", "-"},
+			Manifest:    opts.Manifest,
+		})
+	} else {
+		// Step 1: Create deployment
+		plan.Steps = append(plan.Steps, Step{
+			ID:          "create-deployment",
+			Description: fmt.Sprintf("Create deployment %s", opts.Name),
+			Command:     "kubectl",
+			Args: []string{
+				"create", "deployment", opts.Name,
+				"--image", opts.Image,
+				"--replicas", fmt.Sprintf("%d", opts.Replicas),
+				"-n", opts.Namespace,
+			},
+		})
 
-	// Step 3: Wait for pods ready
+		// Step 2: Expose service
+		plan.Steps = append(plan.Steps, Step{
+			ID:          "create-service",
+			Description: fmt.Sprintf("Expose deployment %s as LoadBalancer", opts.Name),
+			Command:     "kubectl",
+			Args: []string{
+				"expose", "deployment", opts.Name,
+				"--port", fmt.Sprintf("%d", opts.Port),
+				"--type", "LoadBalancer",
+				"-n", opts.Namespace,
+			},
+		})
+	}
+
+	// Jobs run to completion rather than serving
+	switch opts.Type {
+	case "job":
+		return finishJobPlan(plan, opts)
+	case "cronjob":
+		return finishCronJobPlan(plan, opts)
+	}
+
+	// Wait for pods ready
 	plan.Steps = append(plan.Steps, Step{
 		ID:          "wait-pods",
 		Description: "Wait for pods to be running",
@@ -582,7 +735,7 @@ func GenerateDeployPlan(opts DeployOptions) *K8sPlan {
 		},
 	})
 
-	// Step 4: Get service endpoint
+	// Get service endpoint
 	plan.Steps = append(plan.Steps, Step{
 		ID:          "get-endpoint",
 		Description: "Get service endpoint",
@@ -605,6 +758,53 @@ func GenerateDeployPlan(opts DeployOptions) *K8sPlan {
 	return plan
 }
 
+// finishJobPlan waits for a Job applied by GenerateDeployPlan to complete
+func finishJobPlan(plan *K8sPlan, opts DeployOptions) *K8sPlan {
+	plan.Summary = fmt.Sprintf("Run job %s", opts.Name)
+	plan.Notes = []string{
+		fmt.Sprintf("Image: %s", opts.Image),
+		fmt.Sprintf("Namespace: %s", opts.Namespace),
+	}
+	plan.Steps = append(plan.Steps, Step{
+		ID:          "wait-job",
+		Description: fmt.Sprintf("Wait for job %s to complete", opts.Name),
+		Command:     "kubectl",
+		Args:        []string{"wait", "--for=condition=complete", "job/" + opts.Name, "-n", opts.Namespace, "--timeout=10m"},
+	})
+	plan.Connection = &Connection{
+		Commands: []string{
+			fmt.Sprintf("kubectl get job %s -n %s", opts.Name, opts.Namespace),
+			fmt.Sprintf("kubectl logs job/%s -n %s", opts.Name, opts.Namespace),
+		},
+	}
+	return plan
+}
+
+// finishCronJobPlan checks a CronJob applied by GenerateDeployPlan was
+// scheduled; its first run waits for the schedule
+func finishCronJobPlan(plan *K8sPlan, opts DeployOptions) *K8sPlan {
+	plan.Summary = fmt.Sprintf("Schedule cronjob %s (%s)", opts.Name, opts.Schedule)
+	plan.Notes = []string{
+		fmt.Sprintf("Image: %s", opts.Image),
+		fmt.Sprintf("Schedule: %s", opts.Schedule),
+		fmt.Sprintf("Namespace: %s", opts.Namespace),
+	}
+	plan.Steps = append(plan.Steps, Step{
+		ID:          "get-cronjob",
+		Description: fmt.Sprintf("Show cronjob %s", opts.Name),
+		Command:     "kubectl",
+		Args:        []string{"get", "cronjob", opts.Name, "-n", opts.Namespace},
+	})
+	plan.Connection = &Connection{
+		Commands: []string{
+			fmt.Sprintf("kubectl get cronjob %s -n %s", opts.Name, opts.Namespace),
+			fmt.Sprintf("kubectl create job --from=cronjob/%s %s-manual -n %s", opts.Name, opts.Name, opts.Namespace),
+			fmt.Sprintf("kubectl get jobs -n %s", opts.Namespace),
+		},
+	}
+	return plan
+}
+
 // GenerateDeletePlan generates a plan for deleting a cluster
 func GenerateDeletePlan(opts DeleteOptions) *K8sPlan {
 	plan := &K8sPlan{
@@ -678,6 +878,497 @@ func GenerateDeletePlan(opts DeleteOptions) *K8sPlan {
 	return plan
 }
 
+// GenerateUpgradePlan generates a multi-phase plan for upgrading a cluster
+// to a new Kubernetes minor version: pre-flight checks, the control plane,
+// add-ons, the nodes and a final verification
+func GenerateUpgradePlan(opts UpgradeOptions) *K8sPlan {
+	from := ""
+	if opts.CurrentVersion != "" {
+		from = fmt.Sprintf(" from %s", opts.CurrentVersion)
+	}
+
+	plan := &K8sPlan{
+		Version:     CurrentPlanVersion,
+		CreatedAt:   time.Now(),
+		Operation:   "upgrade-cluster",
+		ClusterType: opts.ClusterType,
+		ClusterName: opts.ClusterName,
+		Region:      opts.Region,
+		Profile:     opts.Profile,
+		Summary:     fmt.Sprintf("Upgrade %s cluster '%s'%s to Kubernetes %s", opts.ClusterType, opts.ClusterName, from, opts.TargetVersion),
+		Steps:       []Step{},
+		Notes: []string{
+			"The control plane moves one minor version per upgrade; run the upgrade again for each further version",
+			"Nodes are replaced or drained one at a time and PodDisruptionBudgets are respected",
+			"Check for use of APIs removed in the target version first: kubectl get --raw /metrics | grep apiserver_requested_deprecated_apis",
+			"Node groups already on the target version are skipped, so a failed upgrade can be rerun",
+		},
+	}
+
+	if b := opts.Backup; b != nil {
+		plan.Steps = append(plan.Steps,
+			Step{
+				ID:          "backup-cluster-state",
+				Phase:       UpgradePhasePreflight,
+				Description: fmt.Sprintf("Back up every namespace with Velero as %s", b.Name),
+				Command:     "kubectl",
+				Args:        []string{"apply", "-f", "-"},
+				Manifest:    b.Manifest,
+				Reason:      "Upgrades cannot be rolled back; the backup restores workloads and their volumes",
+			},
+			Step{
+				ID:          "wait-backup",
+				Phase:       UpgradePhasePreflight,
+				Description: "Wait for the backup to complete",
+				Command:     "kubectl",
+				Args:        []string{"wait", "backups.velero.io/" + b.Name, "-n", b.Namespace, "--for=jsonpath={.status.phase}=Completed", "--timeout=30m"},
+				Timeout:     "30m",
+			},
+		)
+	}
+
+	verifyNodes := Step{
+		ID:          "verify-nodes",
+		Phase:       UpgradePhaseVerify,
+		Description: fmt.Sprintf("Verify every node reports kubelet %s and is Ready", opts.TargetVersion),
+		Command:     "kubectl",
+		Args:        []string{"get", "nodes", "-o", "wide"},
+		WaitFor: &WaitConfig{
+			Type:        "node-ready",
+			Timeout:     10 * time.Minute,
+			Interval:    20 * time.Second,
+			Description: "waiting for upgraded nodes to be Ready",
+		},
+	}
+	verifySystemPods := Step{
+		ID:          "verify-system-pods",
+		Phase:       UpgradePhaseVerify,
+		Description: "Verify kube-system pods are running",
+		Command:     "kubectl",
+		Args:        []string{"get", "pods", "-n", "kube-system"},
+	}
+
+	switch opts.ClusterType {
+	case "eks":
+		plan.Steps = append(plan.Steps,
+			Step{
+				ID:          "check-version",
+				Phase:       UpgradePhasePreflight,
+				Description: "Check the current control plane version",
+				Command:     "aws",
+				Args:        []string{"eks", "describe-cluster", "--name", opts.ClusterName, "--query", "cluster.version"},
+			},
+			Step{
+				ID:          "check-addon-versions",
+				Phase:       UpgradePhasePreflight,
+				Description: fmt.Sprintf("List add-on versions compatible with %s", opts.TargetVersion),
+				Command:     "aws",
+				Args:        []string{"eks", "describe-addon-versions", "--kubernetes-version", opts.TargetVersion},
+			},
+			Step{
+				ID:          "upgrade-control-plane",
+				Phase:       UpgradePhaseControlPlane,
+				Description: fmt.Sprintf("Upgrade the EKS control plane to %s", opts.TargetVersion),
+				Command:     "aws",
+				Args:        []string{"eks", "update-cluster-version", "--name", opts.ClusterName, "--kubernetes-version", opts.TargetVersion},
+				Reason:      "The control plane must be upgraded before the nodes",
+				WaitFor: &WaitConfig{
+					Type:        "cluster-ready",
+					Resource:    opts.ClusterName,
+					Timeout:     60 * time.Minute,
+					Interval:    30 * time.Second,
+					Description: "waiting for the control plane update to finish",
+				},
+			},
+			Step{
+				ID:          "list-addons",
+				Phase:       UpgradePhaseAddons,
+				Description: "Review managed add-ons (vpc-cni, coredns, kube-proxy) against the new version",
+				Command:     "aws",
+				Args:        []string{"eks", "list-addons", "--cluster-name", opts.ClusterName},
+				Reason:      "EKS does not upgrade add-ons with the control plane",
+			},
+			Step{
+				ID:          "upgrade-node-groups",
+				Phase:       UpgradePhaseNodes,
+				Description: "Roll each managed node group onto the latest EKS AMI for the new version",
+				Command:     "aws",
+				Args:        []string{"eks", "update-nodegroup-version", "--cluster-name", opts.ClusterName, "--nodegroup-name", "<NODEGROUP>", "--kubernetes-version", opts.TargetVersion},
+				Reason:      "Repeated for every managed node group",
+			},
+			verifyNodes, verifySystemPods)
+
+	case "gke":
+		plan.Steps = append(plan.Steps,
+			Step{
+				ID:          "check-version",
+				Phase:       UpgradePhasePreflight,
+				Description: fmt.Sprintf("Check %s is offered in the cluster's release channel", opts.TargetVersion),
+				Command:     "gcloud",
+				Args:        []string{"container", "get-server-config", "--project", opts.Profile, "--region", opts.Region, "--format", "yaml(validMasterVersions)"},
+			},
+			Step{
+				ID:          "upgrade-control-plane",
+				Phase:       UpgradePhaseControlPlane,
+				Description: fmt.Sprintf("Upgrade the GKE control plane to %s", opts.TargetVersion),
+				Command:     "gcloud",
+				Args:        []string{"container", "clusters", "upgrade", opts.ClusterName, "--master", "--cluster-version", opts.TargetVersion, "--project", opts.Profile, "--region", opts.Region, "--quiet"},
+				Reason:      "GKE upgrades its managed add-ons with the control plane",
+			},
+			Step{
+				ID:          "upgrade-node-pools",
+				Phase:       UpgradePhaseNodes,
+				Description: "Surge-upgrade each node pool to the control plane version",
+				Command:     "gcloud",
+				Args:        []string{"container", "clusters", "upgrade", opts.ClusterName, "--node-pool", "<NODE_POOL>", "--project", opts.Profile, "--region", opts.Region, "--quiet"},
+				Reason:      "Repeated for every node pool",
+			},
+			verifyNodes, verifySystemPods)
+
+	case "aks":
+		plan.Steps = append(plan.Steps,
+			Step{
+				ID:          "check-version",
+				Phase:       UpgradePhasePreflight,
+				Description: fmt.Sprintf("Check %s is an available upgrade", opts.TargetVersion),
+				Command:     "az",
+				Args:        []string{"aks", "get-upgrades", "--name", opts.ClusterName, "--resource-group", opts.Profile, "--output", "table"},
+			},
+			Step{
+				ID:          "upgrade-control-plane",
+				Phase:       UpgradePhaseControlPlane,
+				Description: fmt.Sprintf("Upgrade the AKS control plane to %s", opts.TargetVersion),
+				Command:     "az",
+				Args:        []string{"aks", "upgrade", "--name", opts.ClusterName, "--resource-group", opts.Profile, "--kubernetes-version", opts.TargetVersion, "--control-plane-only", "--yes"},
+				Reason:      "AKS upgrades its managed add-ons with the control plane",
+			},
+			Step{
+				ID:          "upgrade-node-pools",
+				Phase:       UpgradePhaseNodes,
+				Description: "Surge-upgrade each node pool",
+				Command:     "az",
+				Args:        []string{"aks", "nodepool", "upgrade", "--cluster-name", opts.ClusterName, "--name", "<NODE_POOL>", "--resource-group", opts.Profile, "--kubernetes-version", opts.TargetVersion, "--yes"},
+				Reason:      "Repeated for every node pool",
+			},
+			verifyNodes, verifySystemPods)
+
+	case "kubeadm":
+		plan.Steps = append(plan.Steps,
+			Step{
+				ID:          "check-nodes",
+				Phase:       UpgradePhasePreflight,
+				Description: "Check node versions on the first control plane node",
+				Command:     "kubectl",
+				Args:        []string{"get", "nodes", "-o", "wide"},
+			},
+			Step{
+				ID:          "install-kubeadm",
+				Phase:       UpgradePhaseControlPlane,
+				Description: fmt.Sprintf("Install kubeadm %s from pkgs.k8s.io on the first control plane node", opts.TargetVersion),
+				Command:     "apt-get",
+				Args:        []string{"install", "-y", "kubeadm"},
+			},
+			Step{
+				ID:          "upgrade-plan",
+				Phase:       UpgradePhaseControlPlane,
+				Description: "Check the upgrade with kubeadm upgrade plan",
+				Command:     "kubeadm",
+				Args:        []string{"upgrade", "plan"},
+			},
+			Step{
+				ID:          "upgrade-apply",
+				Phase:       UpgradePhaseControlPlane,
+				Description: fmt.Sprintf("Upgrade the control plane to the latest %s patch release", opts.TargetVersion),
+				Command:     "kubeadm",
+				Args:        []string{"upgrade", "apply", "-y", fmt.Sprintf("v%s.<PATCH>", opts.TargetVersion)},
+				Reason:      "Upgrades the API server, controller manager, scheduler and etcd",
+			},
+			Step{
+				ID:          "upgrade-addons",
+				Phase:       UpgradePhaseAddons,
+				Description: "CoreDNS and kube-proxy are upgraded by kubeadm upgrade apply; the CNI is left as installed",
+				Command:     "kubectl",
+				Args:        []string{"get", "daemonsets,deployments", "-n", "kube-system"},
+			},
+			Step{
+				ID:          "upgrade-node-config",
+				Phase:       UpgradePhaseNodes,
+				Description: "Run kubeadm upgrade node on the other control plane nodes and the workers",
+				Command:     "kubeadm",
+				Args:        []string{"upgrade", "node"},
+			},
+			Step{
+				ID:          "drain-node",
+				Phase:       UpgradePhaseNodes,
+				Description: "Drain each node before its kubelet is upgraded",
+				Command:     "kubectl",
+				Args:        []string{"drain", "<NODE>", "--ignore-daemonsets", "--delete-emptydir-data"},
+			},
+			Step{
+				ID:          "upgrade-kubelet",
+				Phase:       UpgradePhaseNodes,
+				Description: fmt.Sprintf("Install kubelet and kubectl %s and restart the kubelet", opts.TargetVersion),
+				Command:     "apt-get",
+				Args:        []string{"install", "-y", "kubelet", "kubectl"},
+			},
+			Step{
+				ID:          "uncordon-node",
+				Phase:       UpgradePhaseNodes,
+				Description: "Uncordon each node once it is Ready",
+				Command:     "kubectl",
+				Args:        []string{"uncordon", "<NODE>"},
+				Reason:      "Nodes are upgraded one at a time: first control plane, other control planes, then workers",
+			},
+			verifyNodes, verifySystemPods)
+	}
+
+	return plan
+}
+
+// GeneratePatchPlan generates a plan for bringing node operating systems up
+// to date one node or node group at a time without changing the Kubernetes
+// version: kubeadm nodes are drained, updated and rebooted over SSH, and
+// managed node groups are rolled onto their latest node image
+func GeneratePatchPlan(opts PatchOptions) *K8sPlan {
+	plan := &K8sPlan{
+		Version:     CurrentPlanVersion,
+		CreatedAt:   time.Now(),
+		Operation:   "patch-nodes",
+		ClusterType: opts.ClusterType,
+		ClusterName: opts.ClusterName,
+		Region:      opts.Region,
+		Profile:     opts.Profile,
+		Summary:     fmt.Sprintf("Patch and reboot the nodes of %s cluster '%s' one at a time", opts.ClusterType, opts.ClusterName),
+		Steps: []Step{
+			{
+				ID:          "check-nodes",
+				Phase:       UpgradePhasePreflight,
+				Description: "Check every node is Ready before patching",
+				Command:     "kubectl",
+				Args:        []string{"get", "nodes", "-o", "wide"},
+			},
+			{
+				ID:          "check-pdbs",
+				Phase:       UpgradePhasePreflight,
+				Description: "Review PodDisruptionBudgets; one that allows no disruptions blocks the drains",
+				Command:     "kubectl",
+				Args:        []string{"get", "poddisruptionbudgets", "-A"},
+			},
+		},
+		Notes: []string{
+			"Nodes are patched one at a time and PodDisruptionBudgets are respected",
+			"The Kubernetes version is unchanged; use clanker k8s upgrade to move it",
+		},
+	}
+
+	switch opts.ClusterType {
+	case "eks":
+		plan.Steps = append(plan.Steps, Step{
+			ID:          "rotate-node-group-ami",
+			Phase:       UpgradePhaseNodes,
+			Description: "Roll each managed node group onto the latest EKS AMI for its version",
+			Command:     "aws",
+			Args:        []string{"eks", "update-nodegroup-version", "--cluster-name", opts.ClusterName, "--nodegroup-name", "<NODEGROUP>"},
+			Reason:      "Repeated for every managed node group; EKS drains and replaces the nodes",
+		})
+		plan.Notes = append(plan.Notes, "Self-managed node groups are not covered; update their launch template AMI")
+
+	case "gke":
+		plan.Steps = append(plan.Steps, Step{
+			ID:          "recreate-node-pool",
+			Phase:       UpgradePhaseNodes,
+			Description: "Recreate each node pool on the latest node image for its current version",
+			Command:     "gcloud",
+			Args:        []string{"container", "clusters", "upgrade", opts.ClusterName, "--node-pool", "<NODE_POOL>", "--cluster-version", "<NODE_POOL_VERSION>", "--project", opts.Profile, "--region", opts.Region, "--quiet"},
+			Reason:      "Repeated for every node pool; GKE surge-replaces the nodes",
+		})
+
+	case "aks":
+		plan.Steps = append(plan.Steps, Step{
+			ID:          "upgrade-node-image",
+			Phase:       UpgradePhaseNodes,
+			Description: "Move each node pool onto the latest node image",
+			Command:     "az",
+			Args:        []string{"aks", "nodepool", "upgrade", "--cluster-name", opts.ClusterName, "--name", "<NODE_POOL>", "--resource-group", opts.Profile, "--node-image-only", "--yes"},
+			Reason:      "Repeated for every node pool; AKS surge-replaces the nodes",
+		})
+
+	case "kubeadm":
+		plan.Steps = append(plan.Steps,
+			Step{
+				ID:          "drain-node",
+				Phase:       UpgradePhaseNodes,
+				Description: "Cordon and drain the node",
+				Command:     "kubectl",
+				Args:        []string{"drain", "<NODE>", "--ignore-daemonsets", "--delete-emptydir-data"},
+			},
+			Step{
+				ID:          "update-packages",
+				Phase:       UpgradePhaseNodes,
+				Description: "Install OS updates over SSH with apt, dnf or yum; kubelet, kubeadm and kubectl are held",
+				Command:     "apt-get",
+				Args:        []string{"-y", "dist-upgrade"},
+			},
+			Step{
+				ID:          "reboot-node",
+				Phase:       UpgradePhaseNodes,
+				Description: "Reboot the node and wait for it to come back",
+				Command:     "systemctl",
+				Args:        []string{"reboot"},
+			},
+			Step{
+				ID:          "uncordon-node",
+				Phase:       UpgradePhaseNodes,
+				Description: "Uncordon the node once it is Ready and wait for evicted pods to be scheduled",
+				Command:     "kubectl",
+				Args:        []string{"uncordon", "<NODE>"},
+				Reason:      "Repeated for every node: workers, the other control plane nodes, then the first control plane node",
+			})
+	}
+
+	plan.Steps = append(plan.Steps,
+		Step{
+			ID:          "verify-nodes",
+			Phase:       UpgradePhaseVerify,
+			Description: "Verify every node is Ready and schedulable",
+			Command:     "kubectl",
+			Args:        []string{"get", "nodes", "-o", "wide"},
+			WaitFor: &WaitConfig{
+				Type:        "node-ready",
+				Timeout:     10 * time.Minute,
+				Interval:    20 * time.Second,
+				Description: "waiting for patched nodes to be Ready",
+			},
+		},
+		Step{
+			ID:          "verify-rescheduled",
+			Phase:       UpgradePhaseVerify,
+			Description: "Verify no pods were left Pending",
+			Command:     "kubectl",
+			Args:        []string{"get", "pods", "-A", "--field-selector=status.phase=Pending"},
+		})
+
+	return plan
+}
+
+// GenerateAddonPlan generates a plan for installing or upgrading an EKS
+// add-on, including the IRSA role its service account assumes
+func GenerateAddonPlan(opts AddonOptions) *K8sPlan {
+	operation, verb := "install-addon", "Install"
+	if opts.Upgrade {
+		operation, verb = "upgrade-addon", "Upgrade"
+	}
+	version := opts.Version
+	if version == "" {
+		version = "<ADDON_VERSION>"
+	}
+	k8sVersion := opts.KubernetesVersion
+	if k8sVersion == "" {
+		k8sVersion = "<K8S_VERSION>"
+	}
+
+	plan := &K8sPlan{
+		Version:     CurrentPlanVersion,
+		CreatedAt:   time.Now(),
+		Operation:   operation,
+		ClusterType: "eks",
+		ClusterName: opts.ClusterName,
+		Region:      opts.Region,
+		Profile:     opts.Profile,
+		Summary:     fmt.Sprintf("%s EKS add-on %s on cluster '%s'", verb, opts.Addon, opts.ClusterName),
+		Steps:       []Step{},
+		Notes: []string{
+			"Add-on versions are tied to the cluster's Kubernetes version; upgrade add-ons after each cluster upgrade",
+		},
+	}
+
+	if opts.KubernetesVersion == "" {
+		plan.Steps = append(plan.Steps, Step{
+			ID:          "get-cluster-version",
+			Description: "Get the cluster's Kubernetes version",
+			Command:     "aws",
+			Args:        []string{"eks", "describe-cluster", "--name", opts.ClusterName, "--query", "cluster.version", "--output", "text"},
+			Produces:    map[string]string{"K8S_VERSION": "cluster.version"},
+		})
+	}
+
+	plan.Steps = append(plan.Steps, Step{
+		ID:          "check-addon-versions",
+		Description: fmt.Sprintf("List %s versions compatible with Kubernetes %s", opts.Addon, k8sVersion),
+		Command:     "aws",
+		Args:        []string{"eks", "describe-addon-versions", "--addon-name", opts.Addon, "--kubernetes-version", k8sVersion},
+		Produces:    map[string]string{"ADDON_VERSION": "addonVersion"},
+	})
+
+	var roleArg []string
+	if opts.PolicyARN != "" {
+		roleName := IRSARoleName(opts.ClusterName, opts.Addon)
+		plan.Steps = append(plan.Steps, irsaRoleSteps(opts.ClusterName, opts.Region, roleName, opts.Namespace, opts.ServiceAccount)...)
+		plan.Steps = append(plan.Steps, Step{
+			ID:          "attach-role-policy",
+			Description: fmt.Sprintf("Attach %s to %s", opts.PolicyARN, roleName),
+			Command:     "aws",
+			Args:        []string{"iam", "attach-role-policy", "--role-name", roleName, "--policy-arn", opts.PolicyARN},
+		})
+		roleArg = []string{"--service-account-role-arn", "<ROLE_ARN>"}
+		plan.Notes = append(plan.Notes,
+			fmt.Sprintf("%s runs as %s/%s, which assumes IAM role %s through IRSA", opts.Addon, opts.Namespace, opts.ServiceAccount, roleName))
+	}
+
+	if opts.Upgrade {
+		plan.Steps = append(plan.Steps, Step{
+			ID:          "update-addon",
+			Description: fmt.Sprintf("Upgrade %s to %s", opts.Addon, version),
+			Command:     "aws",
+			Args: append([]string{
+				"eks", "update-addon",
+				"--cluster-name", opts.ClusterName,
+				"--addon-name", opts.Addon,
+				"--addon-version", version,
+				"--resolve-conflicts", "PRESERVE",
+			}, roleArg...),
+			Reason: "PRESERVE keeps configuration changed on the cluster",
+			WaitFor: &WaitConfig{
+				Type:        "addon-active",
+				Resource:    opts.Addon,
+				Timeout:     20 * time.Minute,
+				Interval:    15 * time.Second,
+				Description: fmt.Sprintf("waiting for %s to finish updating", opts.Addon),
+			},
+		})
+	} else {
+		plan.Steps = append(plan.Steps, Step{
+			ID:          "create-addon",
+			Description: fmt.Sprintf("Install %s %s", opts.Addon, version),
+			Command:     "aws",
+			Args: append([]string{
+				"eks", "create-addon",
+				"--cluster-name", opts.ClusterName,
+				"--addon-name", opts.Addon,
+				"--addon-version", version,
+				"--resolve-conflicts", "OVERWRITE",
+			}, roleArg...),
+			Reason: "OVERWRITE adopts the self-managed copy EKS installs on new clusters",
+			WaitFor: &WaitConfig{
+				Type:        "addon-active",
+				Resource:    opts.Addon,
+				Timeout:     20 * time.Minute,
+				Interval:    15 * time.Second,
+				Description: fmt.Sprintf("waiting for %s to become active", opts.Addon),
+			},
+		})
+	}
+
+	plan.Steps = append(plan.Steps, Step{
+		ID:          "verify-addon",
+		Description: fmt.Sprintf("Verify %s is active and healthy", opts.Addon),
+		Command:     "aws",
+		Args:        []string{"eks", "describe-addon", "--cluster-name", opts.ClusterName, "--addon-name", opts.Addon, "--query", "addon.[status,addonVersion,health]"},
+	})
+
+	return plan
+}
+
 // Bootstrap scripts
 
 func bootstrapNodeScript(k8sVersion string) string {
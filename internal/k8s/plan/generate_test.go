@@ -0,0 +1,308 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGenerateUpgradePlan(t *testing.T) {
+	for _, clusterType := range []string{"eks", "gke", "aks", "kubeadm"} {
+		p := GenerateUpgradePlan(UpgradeOptions{
+			ClusterType:    clusterType,
+			ClusterName:    "prod",
+			Region:         "us-east-1",
+			CurrentVersion: "1.29",
+			TargetVersion:  "1.30",
+		})
+
+		if p.Operation != "upgrade-cluster" {
+			t.Errorf("%s: operation = %q", clusterType, p.Operation)
+		}
+		if !strings.Contains(p.Summary, "from 1.29 to Kubernetes 1.30") {
+			t.Errorf("%s: summary = %q", clusterType, p.Summary)
+		}
+
+		// Every step belongs to a phase and phases never go backwards
+		order := map[string]int{
+			UpgradePhasePreflight:    0,
+			UpgradePhaseControlPlane: 1,
+			UpgradePhaseAddons:       2,
+			UpgradePhaseNodes:        3,
+			UpgradePhaseVerify:       4,
+		}
+		last := -1
+		for _, step := range p.Steps {
+			rank, ok := order[step.Phase]
+			if !ok {
+				t.Errorf("%s: step %s has phase %q", clusterType, step.ID, step.Phase)
+				continue
+			}
+			if rank < last {
+				t.Errorf("%s: step %s in phase %q runs after a later phase", clusterType, step.ID, step.Phase)
+			}
+			last = rank
+		}
+		if last != order[UpgradePhaseVerify] {
+			t.Errorf("%s: plan does not end with verification", clusterType)
+		}
+	}
+}
+
+func TestGenerateUpgradePlanBackup(t *testing.T) {
+	opts := UpgradeOptions{ClusterType: "eks", ClusterName: "prod", TargetVersion: "1.30"}
+	if p := GenerateUpgradePlan(opts); p.Steps[0].ID == "backup-cluster-state" {
+		t.Error("plan without Velero backs up")
+	}
+
+	opts.Backup = &UpgradeBackup{Name: "pre-upgrade-prod-20240101-1200", Namespace: "velero", Manifest: "kind: Backup\n"}
+	p := GenerateUpgradePlan(opts)
+	if p.Steps[0].ID != "backup-cluster-state" || p.Steps[0].Manifest != "kind: Backup\n" || p.Steps[0].Phase != UpgradePhasePreflight {
+		t.Fatalf("first step = %+v", p.Steps[0])
+	}
+	wait := strings.Join(p.Steps[1].Args, " ")
+	if !strings.Contains(wait, "backups.velero.io/pre-upgrade-prod-20240101-1200 -n velero --for=jsonpath={.status.phase}=Completed") {
+		t.Errorf("wait step = %s", wait)
+	}
+}
+
+func TestGeneratePatchPlan(t *testing.T) {
+	nodeSteps := map[string]string{
+		"eks":     "update-nodegroup-version --cluster-name prod --nodegroup-name <NODEGROUP>",
+		"gke":     "--node-pool <NODE_POOL> --cluster-version <NODE_POOL_VERSION>",
+		"aks":     "--node-image-only",
+		"kubeadm": "drain <NODE>",
+	}
+	for clusterType, want := range nodeSteps {
+		p := GeneratePatchPlan(PatchOptions{ClusterType: clusterType, ClusterName: "prod", Region: "us-east-1", Profile: "shop"})
+		if p.Operation != "patch-nodes" {
+			t.Errorf("%s: operation = %q", clusterType, p.Operation)
+		}
+
+		phases := []string{}
+		var nodeArgs []string
+		for _, step := range p.Steps {
+			if len(phases) == 0 || phases[len(phases)-1] != step.Phase {
+				phases = append(phases, step.Phase)
+			}
+			if step.Phase == UpgradePhaseNodes {
+				nodeArgs = append(nodeArgs, strings.Join(step.Args, " "))
+			}
+		}
+		if strings.Join(phases, ",") != "pre-flight,nodes,verify" {
+			t.Errorf("%s: phases = %v", clusterType, phases)
+		}
+		if !strings.Contains(strings.Join(nodeArgs, "\n"), want) {
+			t.Errorf("%s: node steps = %v, want %q", clusterType, nodeArgs, want)
+		}
+	}
+}
+
+func TestDisplayPlanPhases(t *testing.T) {
+	p := GenerateUpgradePlan(UpgradeOptions{ClusterType: "eks", ClusterName: "prod", TargetVersion: "1.30"})
+
+	var buf bytes.Buffer
+	DisplayPlan(&buf, p, PlanDisplayOptions{})
+	out := buf.String()
+
+	if !strings.Contains(out, "=== K8s Cluster Upgrade Plan ===") {
+		t.Errorf("missing header:\n%s", out)
+	}
+	control := strings.Index(out, "[control plane]")
+	nodes := strings.Index(out, "[nodes]")
+	if control == -1 || nodes == -1 || control > nodes {
+		t.Errorf("phase headers missing or out of order:\n%s", out)
+	}
+}
+
+func TestGenerateAddonPlan(t *testing.T) {
+	p := GenerateAddonPlan(AddonOptions{
+		ClusterName:       "prod",
+		Region:            "us-east-1",
+		Addon:             "aws-ebs-csi-driver",
+		KubernetesVersion: "1.30",
+		Namespace:         "kube-system",
+		ServiceAccount:    "ebs-csi-controller-sa",
+		PolicyARN:         "arn:aws:iam::aws:policy/service-role/AmazonEBSCSIDriverPolicy",
+	})
+
+	if p.Operation != "install-addon" {
+		t.Errorf("operation = %q", p.Operation)
+	}
+
+	var ids []string
+	var create Step
+	for _, step := range p.Steps {
+		ids = append(ids, step.ID)
+		if step.ID == "create-addon" {
+			create = step
+		}
+	}
+	want := "check-addon-versions,get-account-id,get-oidc-issuer,check-oidc-provider,associate-oidc-provider,create-irsa-role,attach-role-policy,create-addon,verify-addon"
+	if strings.Join(ids, ",") != want {
+		t.Errorf("steps = %v, want %s", ids, want)
+	}
+	if !strings.Contains(strings.Join(create.Args, " "), "--service-account-role-arn <ROLE_ARN>") {
+		t.Errorf("create-addon args = %v", create.Args)
+	}
+}
+
+func TestGenerateAddonPlanUpgradeWithoutIRSA(t *testing.T) {
+	p := GenerateAddonPlan(AddonOptions{ClusterName: "prod", Addon: "coredns", Upgrade: true})
+
+	if p.Operation != "upgrade-addon" {
+		t.Errorf("operation = %q", p.Operation)
+	}
+	for _, step := range p.Steps {
+		if step.ID == "create-irsa-role" {
+			t.Error("coredns plan should not create an IAM role")
+		}
+	}
+	if p.Steps[0].ID != "get-cluster-version" {
+		t.Errorf("first step = %s, want get-cluster-version when the version is unknown", p.Steps[0].ID)
+	}
+}
+
+func TestIRSATrustPolicy(t *testing.T) {
+	policy := IRSATrustPolicy("123456789012", "oidc.eks.us-east-1.amazonaws.com/id/ABC", "kube-system", "ebs-csi-controller-sa")
+
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		t.Fatalf("trust policy is not valid JSON: %v", err)
+	}
+	for _, want := range []string{
+		"arn:aws:iam::123456789012:oidc-provider/oidc.eks.us-east-1.amazonaws.com/id/ABC",
+		`"oidc.eks.us-east-1.amazonaws.com/id/ABC:sub":"system:serviceaccount:kube-system:ebs-csi-controller-sa"`,
+	} {
+		if !strings.Contains(policy, want) {
+			t.Errorf("trust policy missing %s:\n%s", want, policy)
+		}
+	}
+
+	if got := IRSARoleName(strings.Repeat("c", 60), "aws-ebs-csi-driver"); len(got) != 64 {
+		t.Errorf("role name length = %d, want 64", len(got))
+	}
+}
+
+func TestGenerateDeployPlanWithManifest(t *testing.T) {
+	manifest := "apiVersion: apps/v1\nkind: Deployment\n"
+	p := GenerateDeployPlan(DeployOptions{Name: "api", Image: "myorg/api:1", Port: 8080, Replicas: 2, Namespace: "shop", Manifest: manifest})
+
+	if p.Steps[0].ID != "apply-manifests" || p.Steps[0].Manifest != manifest {
+		t.Fatalf("first step = %+v, want apply-manifests with the manifest", p.Steps[0])
+	}
+	for _, step := range p.Steps {
+		if step.ID == "create-deployment" || step.ID == "create-service" {
+			t.Errorf("unexpected imperative step %s", step.ID)
+		}
+	}
+
+	mp := p.ToMakerPlan("deploy api")
+	got := mp.Commands[0]
+	if strings.Join(got.Args, " ") != "kubectl apply -n shop -f -" || got.Stdin != manifest {
+		t.Errorf("maker command = %+v", got)
+	}
+	if mp.Commands[1].Stdin != "" {
+		t.Errorf("stdin leaked into %v", mp.Commands[1].Args)
+	}
+}
+
+func TestGenerateDeployPlanCreatesPullSecret(t *testing.T) {
+	opts := DeployOptions{Name: "api", Image: "ghcr.io/acme/api:1", Port: 8080, Replicas: 1, Namespace: "shop", Manifest: "kind: Deployment\n",
+		PullSecret: "ghcr-io-pull", PullSecretConfig: "/home/dev/.docker/config.json"}
+	p := GenerateDeployPlan(opts)
+	want := "create secret generic ghcr-io-pull --type kubernetes.io/dockerconfigjson --from-file .dockerconfigjson=/home/dev/.docker/config.json -n shop"
+	if p.Steps[0].ID != "create-pull-secret" || strings.Join(p.Steps[0].Args, " ") != want || p.Steps[1].ID != "apply-manifests" {
+		t.Fatalf("steps = %+v", p.Steps)
+	}
+
+	// An existing secret is only referenced
+	opts.PullSecretConfig = ""
+	if p := GenerateDeployPlan(opts); p.Steps[0].ID != "apply-manifests" {
+		t.Fatalf("steps = %+v, want no secret step", p.Steps)
+	}
+}
+
+func TestGenerateDeployPlanForJobs(t *testing.T) {
+	p := GenerateDeployPlan(DeployOptions{Name: "migrate", Image: "myorg/migrate:3", Namespace: "shop", Type: "job", Manifest: "kind: Job\n"})
+	if last := p.Steps[len(p.Steps)-1]; last.ID != "wait-job" || strings.Join(last.Args, " ") != "wait --for=condition=complete job/migrate -n shop --timeout=10m" {
+		t.Errorf("job steps = %+v", p.Steps)
+	}
+
+	p = GenerateDeployPlan(DeployOptions{Name: "backup", Image: "postgres:16", Namespace: "db", Type: "cronjob", Schedule: "0 2 * * *", Manifest: "kind: CronJob\n"})
+	if p.Summary != "Schedule cronjob backup (0 2 * * *)" || len(p.Steps) != 2 || p.Steps[1].ID != "get-cronjob" {
+		t.Errorf("cronjob plan = %s, steps %+v", p.Summary, p.Steps)
+	}
+	for _, step := range p.Steps {
+		if step.ID == "get-endpoint" || step.ID == "wait-pods" {
+			t.Errorf("cronjob plan has serving step %s", step.ID)
+		}
+	}
+}
+
+func TestGenerateGKECreatePlanOptions(t *testing.T) {
+	p := GenerateGKECreatePlan(GKECreateOptions{
+		ClusterName:    "auto",
+		Project:        "my-project",
+		Region:         "us-central1",
+		NodeCount:      1,
+		NodeType:       "e2-standard-2",
+		Autopilot:      true,
+		ReleaseChannel: "stable",
+	})
+	create := strings.Join(p.Steps[0].Args, " ")
+	if create != "container clusters create-auto auto --project my-project --region us-central1 --release-channel stable" {
+		t.Errorf("autopilot create args = %q", create)
+	}
+	if p.Summary != "Create GKE Autopilot cluster 'auto'" {
+		t.Errorf("summary = %q", p.Summary)
+	}
+
+	p = GenerateGKECreatePlan(GKECreateOptions{
+		ClusterName:                 "std",
+		Project:                     "my-project",
+		Region:                      "us-central1",
+		NodeCount:                   2,
+		NodeType:                    "e2-standard-2",
+		AutoProvisioningMaxCPU:      32,
+		AutoProvisioningMaxMemoryGB: 128,
+	})
+	if create := strings.Join(p.Steps[0].Args, " "); !strings.Contains(create, "--num-nodes 2 --machine-type e2-standard-2 --enable-autoprovisioning --max-cpu 32 --max-memory 128") {
+		t.Errorf("auto-provisioning create args = %q", create)
+	}
+}
+
+func TestGenerateKubeadmCreatePlanSSHAccess(t *testing.T) {
+	p := GenerateKubeadmCreatePlan(KubeadmCreateOptions{
+		ClusterName: "private",
+		WorkerCount: 1,
+		SSHKeyPath:  "/keys/id",
+		SSHUser:     "admin",
+		JumpHost:    "bastion.example.com",
+	})
+	var sshSteps int
+	for _, step := range p.Steps {
+		if step.SSHConfig != nil {
+			sshSteps++
+			if step.SSHConfig.User != "admin" || step.SSHConfig.JumpHost != "bastion.example.com" {
+				t.Errorf("step %s ssh config = %+v", step.ID, step.SSHConfig)
+			}
+		}
+		if step.ID == "get-kubeconfig" {
+			if args := strings.Join(step.Args, " "); !strings.Contains(args, "-J bastion.example.com admin@<CONTROL_PLANE_IP>:.kube/config") {
+				t.Errorf("scp args = %s", args)
+			}
+		}
+	}
+	if sshSteps == 0 {
+		t.Fatal("plan has no SSH steps")
+	}
+
+	p = GenerateKubeadmCreatePlan(KubeadmCreateOptions{ClusterName: "default"})
+	for _, step := range p.Steps {
+		if step.SSHConfig != nil && step.SSHConfig.User != "ubuntu" {
+			t.Errorf("default user = %s", step.SSHConfig.User)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WorkloadIdentityOptions holds options for giving a Kubernetes service
+// account access to a cloud storage bucket
+type WorkloadIdentityOptions struct {
+	ClusterType    string // eks (IRSA) or gke (Workload Identity)
+	ClusterName    string
+	Region         string // AWS region or GKE location
+	Project        string // GCP project, gke only
+	Namespace      string
+	ServiceAccount string
+	Bucket         string
+	ReadOnly       bool
+}
+
+// GenerateWorkloadIdentityPlan generates the steps that let pods running as
+// a service account reach a bucket without static credentials: IRSA on EKS,
+// Workload Identity on GKE. The last step applies the annotated
+// ServiceAccount.
+func GenerateWorkloadIdentityPlan(opts WorkloadIdentityOptions) *K8sPlan {
+	access := "read/write"
+	if opts.ReadOnly {
+		access = "read-only"
+	}
+
+	plan := &K8sPlan{
+		Version:     CurrentPlanVersion,
+		CreatedAt:   time.Now(),
+		Operation:   "workload-identity",
+		ClusterType: opts.ClusterType,
+		ClusterName: opts.ClusterName,
+		Region:      opts.Region,
+		Profile:     opts.Project,
+		Summary: fmt.Sprintf("Give service account %s/%s %s access to bucket %s",
+			opts.Namespace, opts.ServiceAccount, access, opts.Bucket),
+		Steps: []Step{},
+		Notes: []string{
+			"Pods pick up the identity when they start; restart workloads already running as this service account",
+		},
+	}
+
+	switch opts.ClusterType {
+	case "gke":
+		gsa := GCPServiceAccountName(opts.ServiceAccount)
+		gsaEmail := fmt.Sprintf("%s@%s.iam.gserviceaccount.com", gsa, opts.Project)
+		pool := opts.Project + ".svc.id.goog"
+		role := "roles/storage.objectUser"
+		if opts.ReadOnly {
+			role = "roles/storage.objectViewer"
+		}
+
+		plan.Steps = append(plan.Steps,
+			Step{
+				ID:          "check-workload-pool",
+				Description: "Check Workload Identity is enabled on the cluster",
+				Command:     "gcloud",
+				Args: []string{
+					"container", "clusters", "describe", opts.ClusterName,
+					"--location", opts.Region, "--project", opts.Project,
+					"--format", "value(workloadIdentityConfig.workloadPool)",
+				},
+				Produces: map[string]string{"WORKLOAD_POOL": "workloadPool"},
+			},
+			Step{
+				ID:          "enable-workload-identity",
+				Description: fmt.Sprintf("Enable Workload Identity with pool %s", pool),
+				Command:     "gcloud",
+				Args: []string{
+					"container", "clusters", "update", opts.ClusterName,
+					"--location", opts.Region, "--project", opts.Project,
+					"--workload-pool", pool,
+				},
+				Reason: "Skip when the previous step printed the pool; node pools also need --workload-metadata=GKE_METADATA",
+			},
+			Step{
+				ID:          "create-gcp-service-account",
+				Description: fmt.Sprintf("Create Google service account %s", gsaEmail),
+				Command:     "gcloud",
+				Args:        []string{"iam", "service-accounts", "create", gsa, "--project", opts.Project},
+			},
+			Step{
+				ID:          "grant-bucket-access",
+				Description: fmt.Sprintf("Grant %s on gs://%s", role, opts.Bucket),
+				Command:     "gcloud",
+				Args: []string{
+					"storage", "buckets", "add-iam-policy-binding", "gs://" + opts.Bucket,
+					"--member", "serviceAccount:" + gsaEmail,
+					"--role", role,
+				},
+			},
+			Step{
+				ID:          "bind-workload-identity",
+				Description: fmt.Sprintf("Let %s/%s impersonate %s", opts.Namespace, opts.ServiceAccount, gsaEmail),
+				Command:     "gcloud",
+				Args: []string{
+					"iam", "service-accounts", "add-iam-policy-binding", gsaEmail,
+					"--project", opts.Project,
+					"--role", "roles/iam.workloadIdentityUser",
+					"--member", fmt.Sprintf("serviceAccount:%s[%s/%s]", pool, opts.Namespace, opts.ServiceAccount),
+				},
+				Reason: "Only this Kubernetes service account can act as the Google service account",
+			},
+			serviceAccountStep(opts.Namespace, opts.ServiceAccount, "iam.gke.io/gcp-service-account", gsaEmail),
+		)
+
+	default:
+		roleName := IRSARoleName(opts.ClusterName, opts.ServiceAccount)
+		policyName := fmt.Sprintf("%s-s3-access", opts.Bucket)
+
+		plan.Steps = append(plan.Steps, irsaRoleSteps(opts.ClusterName, opts.Region, roleName, opts.Namespace, opts.ServiceAccount)...)
+		plan.Steps = append(plan.Steps,
+			Step{
+				ID:          "put-bucket-policy",
+				Description: fmt.Sprintf("Allow %s %s access to s3://%s", roleName, access, opts.Bucket),
+				Command:     "aws",
+				Args: []string{
+					"iam", "put-role-policy",
+					"--role-name", roleName,
+					"--policy-name", policyName,
+					"--policy-document", S3AccessPolicy(opts.Bucket, opts.ReadOnly),
+				},
+				Reason: "An inline policy scoped to the one bucket",
+			},
+			serviceAccountStep(opts.Namespace, opts.ServiceAccount, "eks.amazonaws.com/role-arn",
+				fmt.Sprintf("arn:aws:iam::<ACCOUNT_ID>:role/%s", roleName)),
+		)
+	}
+
+	return plan
+}
+
+// irsaRoleSteps returns the steps that create an IAM role a service account
+// can assume: look up the cluster's OIDC issuer, make sure it is registered
+// with IAM, and create the role with a trust policy for the service account
+func irsaRoleSteps(clusterName, region, roleName, namespace, serviceAccount string) []Step {
+	associateArgs := []string{"utils", "associate-iam-oidc-provider", "--cluster", clusterName, "--approve"}
+	if region != "" {
+		associateArgs = append(associateArgs, "--region", region)
+	}
+
+	return []Step{
+		{
+			ID:          "get-account-id",
+			Description: "Get the AWS account ID",
+			Command:     "aws",
+			Args:        []string{"sts", "get-caller-identity", "--query", "Account", "--output", "text"},
+			Produces:    map[string]string{"ACCOUNT_ID": "Account"},
+		},
+		{
+			ID:          "get-oidc-issuer",
+			Description: "Get the cluster's OIDC issuer (OIDC_PROVIDER is the issuer URL without https://)",
+			Command:     "aws",
+			Args:        []string{"eks", "describe-cluster", "--name", clusterName, "--query", "cluster.identity.oidc.issuer", "--output", "text"},
+			Produces:    map[string]string{"OIDC_PROVIDER": "cluster.identity.oidc.issuer"},
+		},
+		{
+			ID:          "check-oidc-provider",
+			Description: "Check the OIDC issuer is registered as an IAM identity provider",
+			Command:     "aws",
+			Args:        []string{"iam", "get-open-id-connect-provider", "--open-id-connect-provider-arn", "arn:aws:iam::<ACCOUNT_ID>:oidc-provider/<OIDC_PROVIDER>"},
+		},
+		{
+			ID:          "associate-oidc-provider",
+			Description: "Register the OIDC issuer as an IAM identity provider",
+			Command:     "eksctl",
+			Args:        associateArgs,
+			Reason:      "IRSA lets service accounts assume IAM roles through the cluster's OIDC provider; this is a no-op when already registered",
+		},
+		{
+			ID:          "create-irsa-role",
+			Description: fmt.Sprintf("Create IAM role %s for %s/%s", roleName, namespace, serviceAccount),
+			Command:     "aws",
+			Args: []string{
+				"iam", "create-role",
+				"--role-name", roleName,
+				"--assume-role-policy-document", IRSATrustPolicy("<ACCOUNT_ID>", "<OIDC_PROVIDER>", namespace, serviceAccount),
+			},
+			Reason:   "The trust policy only lets this service account assume the role",
+			Produces: map[string]string{"ROLE_ARN": "Role.Arn"},
+		},
+	}
+}
+
+// serviceAccountStep applies a ServiceAccount carrying the identity annotation
+func serviceAccountStep(namespace, name, annotation, value string) Step {
+	return Step{
+		ID:          "apply-service-account",
+		Description: fmt.Sprintf("Annotate service account %s/%s with %s", namespace, name, annotation),
+		Command:     "kubectl",
+		Args:        []string{"apply", "-f", fmt.Sprintf("serviceaccount-%s-%s.yaml", namespace, name)},
+		ConfigChange: &ConfigChange{
+			File:        fmt.Sprintf("serviceaccount-%s-%s.yaml", namespace, name),
+			Description: "ServiceAccount manifest",
+			After:       ServiceAccountManifest(namespace, name, annotation, value),
+		},
+	}
+}
+
+// ServiceAccountManifest renders a ServiceAccount with one annotation
+func ServiceAccountManifest(namespace, name, annotation, value string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: %s
+  namespace: %s
+  annotations:
+    %s: %s
+`, name, namespace, annotation, value)
+}
+
+// IRSARoleName names the IAM role of an IRSA service account; IAM role names
+// are limited to 64 characters
+func IRSARoleName(clusterName, serviceName string) string {
+	name := fmt.Sprintf("%s-%s-irsa", clusterName, serviceName)
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// IRSATrustPolicy returns the trust policy that lets one service account
+// assume a role through the cluster's OIDC provider. oidcProvider is the
+// issuer URL without https://.
+func IRSATrustPolicy(accountID, oidcProvider, namespace, serviceAccount string) string {
+	return fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Federated":"arn:aws:iam::%[1]s:oidc-provider/%[2]s"},"Action":"sts:AssumeRoleWithWebIdentity","Condition":{"StringEquals":{"%[2]s:sub":"system:serviceaccount:%[3]s:%[4]s","%[2]s:aud":"sts.amazonaws.com"}}}]}`,
+		accountID, oidcProvider, namespace, serviceAccount)
+}
+
+// S3AccessPolicy returns an IAM policy for one bucket and its objects
+func S3AccessPolicy(bucket string, readOnly bool) string {
+	objectActions := []string{"s3:GetObject"}
+	if !readOnly {
+		objectActions = append(objectActions, "s3:PutObject", "s3:DeleteObject")
+	}
+	return fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:ListBucket","s3:GetBucketLocation"],"Resource":"arn:aws:s3:::%[1]s"},{"Effect":"Allow","Action":["%[2]s"],"Resource":"arn:aws:s3:::%[1]s/*"}]}`,
+		bucket, strings.Join(objectActions, `","`))
+}
+
+// GCPServiceAccountName derives a Google service account ID (6-30
+// characters, lowercase letters, digits and hyphens, starting with a letter)
+// from a Kubernetes service account name
+func GCPServiceAccountName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	id := strings.Trim(b.String(), "-")
+	if id == "" || id[0] < 'a' || id[0] > 'z' {
+		id = "ksa-" + id
+	}
+	if len(id) < 6 {
+		id += "-ksa"
+		for len(id) < 6 {
+			id += "0"
+		}
+	}
+	if len(id) > 30 {
+		id = strings.TrimRight(id[:30], "-")
+	}
+	return id
+}
@@ -0,0 +1,88 @@
+package plan
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGenerateWorkloadIdentityPlanEKS(t *testing.T) {
+	p := GenerateWorkloadIdentityPlan(WorkloadIdentityOptions{
+		ClusterType:    "eks",
+		ClusterName:    "prod",
+		Region:         "us-east-1",
+		Namespace:      "media",
+		ServiceAccount: "uploader",
+		Bucket:         "media-assets",
+		ReadOnly:       true,
+	})
+
+	var ids []string
+	for _, step := range p.Steps {
+		ids = append(ids, step.ID)
+	}
+	want := "get-account-id,get-oidc-issuer,check-oidc-provider,associate-oidc-provider,create-irsa-role,put-bucket-policy,apply-service-account"
+	if strings.Join(ids, ",") != want {
+		t.Errorf("steps = %v, want %s", ids, want)
+	}
+
+	last := p.Steps[len(p.Steps)-1]
+	if last.ConfigChange == nil || !strings.Contains(last.ConfigChange.After, "eks.amazonaws.com/role-arn") {
+		t.Errorf("service account step = %+v", last)
+	}
+}
+
+func TestGenerateWorkloadIdentityPlanGKE(t *testing.T) {
+	p := GenerateWorkloadIdentityPlan(WorkloadIdentityOptions{
+		ClusterType:    "gke",
+		ClusterName:    "data",
+		Region:         "europe-west1",
+		Project:        "acme-data",
+		Namespace:      "analytics",
+		ServiceAccount: "reports",
+		Bucket:         "analytics-exports",
+	})
+
+	var grant Step
+	for _, step := range p.Steps {
+		if step.ID == "grant-bucket-access" {
+			grant = step
+		}
+	}
+	args := strings.Join(grant.Args, " ")
+	if !strings.Contains(args, "gs://analytics-exports") || !strings.Contains(args, "roles/storage.objectUser") {
+		t.Errorf("grant args = %s", args)
+	}
+}
+
+func TestS3AccessPolicy(t *testing.T) {
+	for _, readOnly := range []bool{true, false} {
+		policy := S3AccessPolicy("media-assets", readOnly)
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+			t.Fatalf("policy is not valid JSON: %v\n%s", err, policy)
+		}
+		if strings.Contains(policy, "s3:PutObject") == readOnly {
+			t.Errorf("readOnly=%v policy: %s", readOnly, policy)
+		}
+		if !strings.Contains(policy, "arn:aws:s3:::media-assets/*") {
+			t.Errorf("policy not scoped to the bucket: %s", policy)
+		}
+	}
+}
+
+func TestGCPServiceAccountName(t *testing.T) {
+	tests := map[string]string{
+		"reports":            "reports",
+		"ab":                 "ab-ksa",
+		"a":                  "a-ksa0",
+		"1worker":            "ksa-1worker",
+		"my.service_account": "my-service-account",
+		"a-very-long-service-account-name-indeed": "a-very-long-service-account-na",
+	}
+	for in, want := range tests {
+		if got := GCPServiceAccountName(in); got != want {
+			t.Errorf("GCPServiceAccountName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -176,7 +176,12 @@ func DisplayPlan(w io.Writer, plan *K8sPlan, opts PlanDisplayOptions) {
 	fmt.Fprintln(w)
 
 	fmt.Fprintln(w, "Steps:")
+	phase := ""
 	for i, step := range plan.Steps {
+		if step.Phase != "" && step.Phase != phase {
+			phase = step.Phase
+			fmt.Fprintf(w, "  [%s]\n", phase)
+		}
 		fmt.Fprintf(w, "  %d. %s\n", i+1, step.Description)
 		if opts.ShowCommands && len(step.Args) > 0 {
 			cmdStr := strings.Join(step.Args, " ")
@@ -270,6 +275,14 @@ func formatOperation(op string) string {
 		return "Cluster Creation"
 	case "delete-cluster":
 		return "Cluster Deletion"
+	case "upgrade-cluster":
+		return "Cluster Upgrade"
+	case "install-addon":
+		return "Add-on Installation"
+	case "upgrade-addon":
+		return "Add-on Upgrade"
+	case "workload-identity":
+		return "Workload Identity"
 	case "deploy":
 		return "Deployment"
 	case "scale":
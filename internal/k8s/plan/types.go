@@ -28,6 +28,8 @@ type MakerCommand struct {
 	Args     []string          `json:"args"`
 	Reason   string            `json:"reason,omitempty"`
 	Produces map[string]string `json:"produces,omitempty"`
+	Stdin    string            `json:"stdin,omitempty"`   // piped to the command, e.g. a manifest for kubectl apply -f -
+	Timeout  string            `json:"timeout,omitempty"` // e.g. "10m"; empty uses --step-timeout
 }
 
 // ToMakerPlan converts a K8sPlan to AWS maker-compatible format
@@ -50,6 +52,8 @@ func (p *K8sPlan) ToMakerPlan(question string) *MakerPlan {
 			Args:     args,
 			Reason:   step.Reason,
 			Produces: step.Produces,
+			Stdin:    step.Manifest,
+			Timeout:  step.Timeout,
 		}
 
 		// Add description as reason if reason is empty
@@ -111,7 +115,7 @@ func sanitizeFilename(name string) string {
 type K8sPlan struct {
 	Version     int         `json:"version"`
 	CreatedAt   time.Time   `json:"createdAt"`
-	Operation   string      `json:"operation"`   // create-cluster, deploy, scale, delete
+	Operation   string      `json:"operation"`   // create-cluster, upgrade-cluster, install-addon, upgrade-addon, workload-identity, deploy, scale, delete
 	ClusterType string      `json:"clusterType"` // eks, kubeadm, k3s
 	ClusterName string      `json:"clusterName"`
 	Region      string      `json:"region"`
@@ -133,11 +137,14 @@ type Step struct {
 	WaitFor      *WaitConfig       `json:"waitFor,omitempty"`
 	ConfigChange *ConfigChange     `json:"configChange,omitempty"`
 	SSHConfig    *SSHStepConfig    `json:"sshConfig,omitempty"`
+	Phase        string            `json:"phase,omitempty"`    // groups steps of multi-phase plans such as upgrades
+	Manifest     string            `json:"manifest,omitempty"` // YAML piped to the command's stdin
+	Timeout      string            `json:"timeout,omitempty"`  // e.g. "10m"; empty uses --step-timeout
 }
 
 // WaitConfig configures async waiting behavior
 type WaitConfig struct {
-	Type        string        `json:"type"` // cluster-ready, node-ready, instance-running
+	Type        string        `json:"type"` // cluster-ready, node-ready, instance-running, addon-active
 	Resource    string        `json:"resource,omitempty"`
 	Timeout     time.Duration `json:"timeout"`
 	Interval    time.Duration `json:"interval"`
@@ -158,6 +165,7 @@ type SSHStepConfig struct {
 	Host       string `json:"host"`
 	User       string `json:"user"`
 	KeyPath    string `json:"keyPath"`
+	JumpHost   string `json:"jumpHost,omitempty"` // bastion, [user@]host[:port]
 	Script     string `json:"script"`
 	ScriptName string `json:"scriptName,omitempty"`
 }
@@ -176,6 +184,9 @@ type ExecOptions struct {
 	Debug      bool
 	DryRun     bool
 	SSHKeyPath string
+	// SSHUser and SSHJumpHost apply to SSH steps that do not set their own
+	SSHUser     string
+	SSHJumpHost string
 }
 
 // ExecResult holds the result of plan execution
@@ -0,0 +1,69 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+)
+
+// EnforceOptions controls how violations are resolved
+type EnforceOptions struct {
+	// Override applies despite denied operations (--override-policy)
+	Override bool
+	// Approve asks the operator to confirm operations that need approval.
+	// Without it those operations need Override as well.
+	Approve func([]Violation) bool
+	// Source and Summary describe the plan in the audit log
+	Source  string
+	Summary string
+}
+
+// Enforce decides whether a plan with these violations may be applied.
+// Denied operations need Override; operations needing approval need Approve
+// to confirm them or Override. Every override and approval is written to the
+// audit log, and a failed audit write refuses the plan.
+func Enforce(violations []Violation, opts EnforceOptions) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	denied := Denied(violations)
+	if len(denied) > 0 && !opts.Override {
+		return violationsError("policy denies", denied)
+	}
+
+	event := audit.EventPolicyOverride
+	if len(denied) == 0 && !opts.Override {
+		if opts.Approve == nil {
+			return violationsError("policy requires approval for", violations)
+		}
+		if !opts.Approve(violations) {
+			return violationsError("approval declined for", violations)
+		}
+		event = audit.EventPolicyApproval
+	}
+
+	details := make([]string, 0, len(violations))
+	for _, v := range violations {
+		details = append(details, fmt.Sprintf("%s: %s", v.Action, v))
+	}
+	if _, err := audit.Append(audit.Record{
+		Event:   event,
+		Command: opts.Source,
+		Summary: opts.Summary,
+		Details: details,
+	}); err != nil {
+		return fmt.Errorf("refusing to apply past the policy without an audit entry: %w", err)
+	}
+	return nil
+}
+
+// violationsError lists violations under a heading
+func violationsError(heading string, violations []Violation) error {
+	lines := make([]string, 0, len(violations))
+	for _, v := range violations {
+		lines = append(lines, "  "+v.String())
+	}
+	return fmt.Errorf("%s %d operations (use --override-policy to apply anyway):\n%s", heading, len(violations), strings.Join(lines, "\n"))
+}
@@ -0,0 +1,256 @@
+package policy
+
+import (
+	"bufio"
+	"strings"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// AllNamespaces is the namespace of an operation run with --all-namespaces
+const AllNamespaces = "*"
+
+// Operation is one change a plan makes
+type Operation struct {
+	Verb      string `json:"verb"`
+	Resource  string `json:"resource"` // plural lower-case resource, e.g. persistentvolumes
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"` // empty for cluster-scoped resources
+}
+
+func (o Operation) String() string {
+	s := o.Verb
+	if o.Resource != "" {
+		s += " " + o.Resource
+		if o.Name != "" {
+			s += "/" + o.Name
+		}
+	}
+	if o.Namespace == AllNamespaces {
+		s += " in all namespaces"
+	} else if o.Namespace != "" {
+		s += " in " + o.Namespace
+	}
+	return s
+}
+
+// readOnlyVerbs never change the cluster
+var readOnlyVerbs = map[string]bool{
+	"get": true, "describe": true, "logs": true, "top": true, "explain": true,
+	"api-resources": true, "api-versions": true, "version": true, "cluster-info": true,
+	"config": true, "auth": true, "wait": true, "diff": true, "events": true,
+	"port-forward": true, "proxy": true, "completion": true, "kustomize": true,
+	"status": true, "history": true, "list": true, "search": true, "show": true,
+	"repo": true, "template": true, "lint": true, "dependency": true, "plugin": true,
+}
+
+// clusterScoped are the built-in resources without a namespace
+var clusterScoped = map[string]bool{
+	"namespaces": true, "nodes": true, "persistentvolumes": true, "storageclasses": true,
+	"clusterroles": true, "clusterrolebindings": true, "customresourcedefinitions": true,
+	"priorityclasses": true, "ingressclasses": true, "runtimeclasses": true,
+	"validatingwebhookconfigurations": true, "mutatingwebhookconfigurations": true,
+	"apiservices": true, "certificatesigningrequests": true, "csidrivers": true,
+	"csinodes": true, "volumeattachments": true,
+}
+
+// resourceAliases maps kubectl short names and singulars that do not
+// pluralize with a trailing s
+var resourceAliases = map[string]string{
+	"po": "pods", "pv": "persistentvolumes", "pvc": "persistentvolumeclaims",
+	"deploy": "deployments", "svc": "services", "ns": "namespaces", "sts": "statefulsets",
+	"ds": "daemonsets", "rs": "replicasets", "cm": "configmaps", "ing": "ingresses",
+	"no": "nodes", "sa": "serviceaccounts", "hpa": "horizontalpodautoscalers",
+	"pdb": "poddisruptionbudgets", "netpol": "networkpolicies", "sc": "storageclasses",
+	"crd": "customresourcedefinitions", "cj": "cronjobs", "ep": "endpoints",
+	"endpoints": "endpoints", "ingress": "ingresses", "networkpolicy": "networkpolicies",
+	"storageclass": "storageclasses", "priorityclass": "priorityclasses", "ingressclass": "ingressclasses",
+}
+
+// NormalizeResource turns a kubectl resource argument or kind ("pv",
+// "Deployment", "deployments.apps") into its plural lower-case name
+func NormalizeResource(resource string) string {
+	resource = strings.ToLower(strings.TrimSpace(resource))
+	if i := strings.Index(resource, "."); i > 0 {
+		resource = resource[:i]
+	}
+	if alias, ok := resourceAliases[resource]; ok {
+		return alias
+	}
+	switch {
+	case resource == "", strings.HasSuffix(resource, "s"):
+		return resource
+	case strings.HasSuffix(resource, "y"):
+		return strings.TrimSuffix(resource, "y") + "ies"
+	}
+	return resource + "s"
+}
+
+// kubectlValueFlags take a separate value argument
+var kubectlValueFlags = map[string]bool{
+	"-n": true, "--namespace": true, "--context": true, "--kubeconfig": true, "--cluster": true,
+	"--user": true, "-l": true, "--selector": true, "-f": true, "--filename": true, "-o": true,
+	"--output": true, "-c": true, "--container": true, "--replicas": true, "--image": true,
+	"--port": true, "--type": true, "--as": true, "--as-group": true, "-p": true, "--patch": true,
+	"--timeout": true, "--field-selector": true, "--grace-period": true, "--to-revision": true,
+	"--min": true, "--max": true, "--cpu-percent": true, "--target-port": true, "-k": true,
+	"--kustomize": true,
+}
+
+// FromKubectl describes a kubectl invocation (args without the binary). It
+// returns false for read-only commands. Namespaced resources without -n use
+// defaultNamespace.
+func FromKubectl(args []string, defaultNamespace string) (Operation, bool) {
+	var positional []string
+	namespace := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-A" || arg == "--all-namespaces" || arg == "--all-namespaces=true":
+			namespace = AllNamespaces
+		case arg == "-n" || arg == "--namespace":
+			if i+1 < len(args) {
+				namespace = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(arg, "--namespace="):
+			namespace = strings.TrimPrefix(arg, "--namespace=")
+		case strings.HasPrefix(arg, "-n") && len(arg) > 2 && !strings.HasPrefix(arg, "--"):
+			namespace = strings.TrimPrefix(arg[2:], "=")
+		case arg == "--":
+			i = len(args)
+		case strings.HasPrefix(arg, "-"):
+			if kubectlValueFlags[arg] && i+1 < len(args) {
+				i++
+			}
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) == 0 {
+		return Operation{}, false
+	}
+
+	verb := strings.ToLower(positional[0])
+	rest := positional[1:]
+	switch verb {
+	case "rollout", "set", "certificate":
+		// kubectl rollout restart deployment/web, kubectl set image deployment/web
+		if len(rest) == 0 || readOnlyVerbs[rest[0]] {
+			return Operation{}, false
+		}
+		verb = verb + " " + rest[0]
+		rest = rest[1:]
+	case "create":
+		// kubectl create secret generic name: the secret type is not a name
+		if len(rest) >= 2 && (rest[0] == "secret" || rest[0] == "service") {
+			rest = append(rest[:1:1], rest[2:]...)
+		}
+	case "cordon", "uncordon", "drain":
+		rest = append([]string{"nodes"}, rest...)
+	}
+	if readOnlyVerbs[verb] {
+		return Operation{}, false
+	}
+
+	op := Operation{Verb: verb}
+	if len(rest) > 0 {
+		resource, name, _ := strings.Cut(rest[0], "/")
+		op.Resource = NormalizeResource(resource)
+		op.Name = name
+		if op.Name == "" && len(rest) > 1 {
+			op.Name = rest[1]
+		}
+	}
+	op.Namespace = operationNamespace(op, namespace, defaultNamespace)
+	return op, true
+}
+
+// FromHelm describes a helm invocation (args without the binary). It
+// returns false for read-only commands.
+func FromHelm(args []string, defaultNamespace string) (Operation, bool) {
+	var positional []string
+	namespace := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-A" || arg == "--all-namespaces":
+			namespace = AllNamespaces
+		case arg == "-n" || arg == "--namespace":
+			if i+1 < len(args) {
+				namespace = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(arg, "--namespace="):
+			namespace = strings.TrimPrefix(arg, "--namespace=")
+		case arg == "-f" || arg == "--values" || arg == "--set" || arg == "--version" || arg == "--kube-context" || arg == "--repo" || arg == "--timeout":
+			i++
+		case strings.HasPrefix(arg, "-"):
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) == 0 || readOnlyVerbs[positional[0]] {
+		return Operation{}, false
+	}
+	op := Operation{Verb: positional[0], Resource: "helmreleases"}
+	if len(positional) > 1 {
+		op.Name = positional[1]
+	}
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	op.Namespace = namespace
+	return op, true
+}
+
+// FromManifest describes applying each document of a YAML manifest
+func FromManifest(verb, content, defaultNamespace string) []Operation {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(content)))
+	var ops []Operation
+	for {
+		doc, err := reader.Read()
+		if err != nil {
+			break
+		}
+		var obj struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+		if yaml.Unmarshal(doc, &obj) != nil || obj.Kind == "" {
+			continue
+		}
+		op := Operation{Verb: verb, Resource: NormalizeResource(obj.Kind), Name: obj.Metadata.Name}
+		op.Namespace = operationNamespace(op, obj.Metadata.Namespace, defaultNamespace)
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// operationNamespace resolves where an operation lands. Changing a namespace
+// object counts as a change inside that namespace.
+func operationNamespace(op Operation, namespace, defaultNamespace string) string {
+	if op.Resource == "namespaces" {
+		if op.Name != "" {
+			return op.Name
+		}
+		return namespace
+	}
+	if clusterScoped[op.Resource] {
+		return ""
+	}
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	return namespace
+}
@@ -0,0 +1,237 @@
+// Package policy enforces operator guardrails on Kubernetes plans. The policy
+// file (~/.clanker/policy.yaml) declares protected namespaces, where no plan
+// may change anything, and rules that deny or require approval for verbs on
+// resources:
+//
+//	protected_namespaces:
+//	  - kube-system
+//	  - prod-*
+//	rules:
+//	  - name: keep-volumes
+//	    verbs: [delete]
+//	    resources: [persistentvolumes, persistentvolumeclaims]
+//	    reason: volumes hold data
+//	  - name: staging-changes
+//	    verbs: [delete, scale, drain]
+//	    namespaces: [staging-*]
+//	    action: approve
+//
+// A denied operation is refused unless the operator overrides the policy;
+// an operation needing approval is confirmed interactively or overridden.
+// Every override and approval is appended to the audit log.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action is what a matching rule does
+type Action string
+
+const (
+	ActionDeny    Action = "deny"
+	ActionApprove Action = "approve"
+)
+
+// Policy is the parsed policy file
+type Policy struct {
+	ProtectedNamespaces []string `yaml:"protected_namespaces"`
+	Rules               []Rule   `yaml:"rules"`
+}
+
+// Rule matches operations by verb, resource and namespace. An empty list
+// matches anything; namespaces are glob patterns.
+type Rule struct {
+	Name       string   `yaml:"name"`
+	Verbs      []string `yaml:"verbs"`
+	Resources  []string `yaml:"resources"`
+	Namespaces []string `yaml:"namespaces"`
+	Action     Action   `yaml:"action"` // deny (default) or approve
+	Reason     string   `yaml:"reason"`
+}
+
+// Violation is an operation a rule or a protected namespace matched
+type Violation struct {
+	Operation Operation `json:"operation"`
+	Action    Action    `json:"action"`
+	Rule      string    `json:"rule"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+func (v Violation) String() string {
+	s := fmt.Sprintf("%s (%s)", v.Operation, v.Rule)
+	if v.Reason != "" {
+		s += ": " + v.Reason
+	}
+	return s
+}
+
+// DefaultPath returns ~/.clanker/policy.yaml
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "policy.yaml"), nil
+}
+
+// Load reads the policy at DefaultPath. It returns nil without an error when
+// there is no policy file.
+func Load() (*Policy, error) {
+	policyPath, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	p, err := LoadFile(policyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// LoadFile reads and validates a policy file
+func LoadFile(policyPath string) (*Policy, error) {
+	data, err := os.ReadFile(policyPath)
+	if err != nil {
+		return nil, err
+	}
+	var p Policy
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", policyPath, err)
+	}
+	if err := p.normalize(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %w", policyPath, err)
+	}
+	return &p, nil
+}
+
+// normalize fills in defaults, canonicalizes resource names and rejects
+// unknown actions and malformed patterns
+func (p *Policy) normalize() error {
+	for _, pattern := range p.ProtectedNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("protected namespace %q: %w", pattern, err)
+		}
+	}
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		switch r.Action {
+		case "":
+			r.Action = ActionDeny
+		case ActionDeny, ActionApprove:
+		default:
+			return fmt.Errorf("%s: unknown action %q (use deny or approve)", r.Name, r.Action)
+		}
+		for j, verb := range r.Verbs {
+			r.Verbs[j] = strings.ToLower(verb)
+		}
+		for j, res := range r.Resources {
+			if res != "*" {
+				r.Resources[j] = NormalizeResource(res)
+			}
+		}
+		for _, pattern := range r.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%s: namespace %q: %w", r.Name, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Check returns the violations among ops. An operation in a protected
+// namespace is denied outright; otherwise every matching rule reports.
+func (p *Policy) Check(ops []Operation) []Violation {
+	if p == nil {
+		return nil
+	}
+	var violations []Violation
+	for _, op := range ops {
+		if pattern, ok := matchNamespace(p.ProtectedNamespaces, op.Namespace); ok {
+			violations = append(violations, Violation{
+				Operation: op,
+				Action:    ActionDeny,
+				Rule:      "protected namespace " + pattern,
+				Reason:    "no plan may change protected namespaces",
+			})
+			continue
+		}
+		for _, r := range p.Rules {
+			if r.matches(op) {
+				violations = append(violations, Violation{Operation: op, Action: r.Action, Rule: r.Name, Reason: r.Reason})
+			}
+		}
+	}
+	return violations
+}
+
+func (r Rule) matches(op Operation) bool {
+	if len(r.Verbs) > 0 && !containsOrWildcard(r.Verbs, op.Verb) {
+		return false
+	}
+	if len(r.Resources) > 0 && !containsOrWildcard(r.Resources, op.Resource) {
+		return false
+	}
+	if len(r.Namespaces) > 0 {
+		if _, ok := matchNamespace(r.Namespaces, op.Namespace); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func containsOrWildcard(list []string, value string) bool {
+	for _, item := range list {
+		if item == "*" || item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// matchNamespace returns the first pattern matching namespace. An operation
+// across all namespaces matches every pattern; a cluster-scoped one none.
+func matchNamespace(patterns []string, namespace string) (string, bool) {
+	if namespace == "" {
+		return "", false
+	}
+	for _, pattern := range patterns {
+		if namespace == AllNamespaces {
+			return pattern, true
+		}
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// Denied returns the violations that refuse the plan
+func Denied(violations []Violation) []Violation {
+	return filterAction(violations, ActionDeny)
+}
+
+// NeedApproval returns the violations that need an approval
+func NeedApproval(violations []Violation) []Violation {
+	return filterAction(violations, ActionApprove)
+}
+
+func filterAction(violations []Violation, action Action) []Violation {
+	var out []Violation
+	for _, v := range violations {
+		if v.Action == action {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,227 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+)
+
+const testPolicy = `protected_namespaces:
+  - kube-system
+  - prod-*
+rules:
+  - name: keep-volumes
+    verbs: [delete]
+    resources: [pv, PersistentVolumeClaim]
+    reason: volumes hold data
+  - name: staging-changes
+    verbs: [delete, scale]
+    namespaces: [staging-*]
+    action: approve
+`
+
+func loadTestPolicy(t *testing.T, content string) *Policy {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	return p
+}
+
+func TestLoadFileRejectsInvalidPolicies(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown action": "rules:\n  - verbs: [delete]\n    action: warn\n",
+		"bad pattern":    "protected_namespaces: ['prod-[']\n",
+		"not yaml":       "rules: [\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.yaml")
+			if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadFile(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestLoadWithoutPolicyFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p, err := Load()
+	if err != nil || p != nil {
+		t.Fatalf("Load() = %v, %v; want no policy", p, err)
+	}
+	if got := p.Check([]Operation{{Verb: "delete", Resource: "namespaces", Namespace: "kube-system"}}); got != nil {
+		t.Errorf("nil policy reported %v", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p := loadTestPolicy(t, testPolicy)
+
+	tests := []struct {
+		name string
+		op   Operation
+		want []string // "action rule"
+	}{
+		{"protected namespace", Operation{Verb: "apply", Resource: "deployments", Namespace: "kube-system"}, []string{"deny protected namespace kube-system"}},
+		{"protected glob", Operation{Verb: "scale", Resource: "deployments", Namespace: "prod-eu"}, []string{"deny protected namespace prod-*"}},
+		{"all namespaces", Operation{Verb: "delete", Resource: "pods", Namespace: AllNamespaces}, []string{"deny protected namespace kube-system"}},
+		{"forbidden verb", Operation{Verb: "delete", Resource: "persistentvolumes"}, []string{"deny keep-volumes"}},
+		{"forbidden verb alias", Operation{Verb: "delete", Resource: "persistentvolumeclaims", Namespace: "staging-a"}, []string{"deny keep-volumes", "approve staging-changes"}},
+		{"approval", Operation{Verb: "scale", Resource: "deployments", Namespace: "staging-a"}, []string{"approve staging-changes"}},
+		{"allowed", Operation{Verb: "apply", Resource: "deployments", Namespace: "default"}, nil},
+		{"cluster scoped", Operation{Verb: "cordon", Resource: "nodes"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range p.Check([]Operation{tt.op}) {
+				got = append(got, string(v.Action)+" "+v.Rule)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("violations = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromKubectl(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string // Operation.String(), empty for read-only
+	}{
+		{[]string{"get", "pods", "-A"}, ""},
+		{[]string{"rollout", "status", "deployment/web"}, ""},
+		{[]string{"delete", "pv", "data-0"}, "delete persistentvolumes/data-0"},
+		{[]string{"delete", "pvc/data", "-n", "prod-eu"}, "delete persistentvolumeclaims/data in prod-eu"},
+		{[]string{"--namespace=kube-system", "scale", "deploy", "coredns", "--replicas", "0"}, "scale deployments/coredns in kube-system"},
+		{[]string{"delete", "pods", "--all", "--all-namespaces"}, "delete pods in all namespaces"},
+		{[]string{"delete", "namespace", "kube-system"}, "delete namespaces/kube-system in kube-system"},
+		{[]string{"rollout", "restart", "deployment/web"}, "rollout restart deployments/web in default"},
+		{[]string{"create", "secret", "generic", "creds", "-n", "shop"}, "create secrets/creds in shop"},
+		{[]string{"drain", "node-1", "--ignore-daemonsets"}, "drain nodes/node-1"},
+		{[]string{"apply", "-f", "-"}, "apply in default"},
+	}
+	for _, tt := range tests {
+		op, ok := FromKubectl(tt.args, "")
+		got := ""
+		if ok {
+			got = op.String()
+		}
+		if got != tt.want {
+			t.Errorf("FromKubectl(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFromHelm(t *testing.T) {
+	op, ok := FromHelm([]string{"uninstall", "ingress", "--namespace", "kube-system"}, "")
+	if !ok || op.String() != "uninstall helmreleases/ingress in kube-system" {
+		t.Errorf("uninstall = %q, %v", op, ok)
+	}
+	op, ok = FromHelm([]string{"upgrade", "--install", "web", "bitnami/nginx", "-f", "values.yaml"}, "shop")
+	if !ok || op.String() != "upgrade helmreleases/web in shop" {
+		t.Errorf("upgrade = %q, %v", op, ok)
+	}
+	if _, ok := FromHelm([]string{"list", "-A"}, ""); ok {
+		t.Error("helm list is read-only")
+	}
+}
+
+func TestFromManifest(t *testing.T) {
+	content := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod-us
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod-us
+---
+apiVersion: v1
+kind: PersistentVolume
+metadata:
+  name: data
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: deny-all
+`
+	var got []string
+	for _, op := range FromManifest("apply", content, "shop") {
+		got = append(got, op.String())
+	}
+	want := []string{
+		"apply namespaces/prod-us in prod-us",
+		"apply deployments/web in prod-us",
+		"apply persistentvolumes/data",
+		"apply networkpolicies/deny-all in shop",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("operations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestEnforce(t *testing.T) {
+	p := loadTestPolicy(t, testPolicy)
+	denied := p.Check([]Operation{{Verb: "delete", Resource: "persistentvolumes", Name: "data"}})
+	held := p.Check([]Operation{{Verb: "scale", Resource: "deployments", Namespace: "staging-a"}})
+
+	t.Setenv("HOME", t.TempDir())
+	opts := func(override bool, approve func([]Violation) bool) EnforceOptions {
+		return EnforceOptions{Override: override, Approve: approve, Source: "test", Summary: "s"}
+	}
+	yes := func([]Violation) bool { return true }
+	no := func([]Violation) bool { return false }
+
+	if err := Enforce(nil, opts(false, nil)); err != nil {
+		t.Errorf("no violations: %v", err)
+	}
+	if err := Enforce(denied, opts(false, yes)); err == nil || !strings.Contains(err.Error(), "--override-policy") {
+		t.Errorf("denied error = %v", err)
+	}
+	if err := Enforce(held, opts(false, nil)); err == nil {
+		t.Error("approval without a prompt should be refused")
+	}
+	if err := Enforce(held, opts(false, no)); err == nil || !strings.Contains(err.Error(), "declined") {
+		t.Errorf("declined error = %v", err)
+	}
+	if records, err := audit.List(); err != nil || len(records) != 0 {
+		t.Fatalf("refusals should not write the audit log: %v, %v", records, err)
+	}
+
+	if err := Enforce(held, opts(false, yes)); err != nil {
+		t.Errorf("approved: %v", err)
+	}
+	if err := Enforce(denied, opts(true, nil)); err != nil {
+		t.Errorf("override: %v", err)
+	}
+
+	records, err := audit.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("audit log has %d records, want 2", len(records))
+	}
+	if records[0].Event != audit.EventPolicyApproval {
+		t.Errorf("first record = %+v", records[0])
+	}
+	rec := records[1]
+	if rec.Event != audit.EventPolicyOverride || rec.Command != "test" || len(rec.Details) != 1 || !strings.Contains(rec.Details[0], "keep-volumes") {
+		t.Errorf("override record = %+v", rec)
+	}
+}
@@ -0,0 +1,251 @@
+// Package prune tracks the objects a plan applies with a plan-id label, so
+// the objects a later apply no longer contains, or everything a plan
+// created, can be found in the cluster and deleted.
+package prune
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// PlanLabel marks every object a stored plan applies with the plan's ID
+const PlanLabel = "clanker/plan-id"
+
+// K8sClient defines the kubectl operations pruning needs
+// This interface is satisfied by k8s.Client
+type K8sClient interface {
+	Run(ctx context.Context, args ...string) (string, error)
+}
+
+// Object identifies one Kubernetes object
+type Object struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func (o Object) String() string {
+	ref := strings.ToLower(o.Kind) + "/" + o.Name
+	if o.Namespace != "" {
+		return o.Namespace + "/" + ref
+	}
+	return ref
+}
+
+// group returns the API group of the object's apiVersion; empty for core
+func (o Object) group() string {
+	if group, _, ok := strings.Cut(o.APIVersion, "/"); ok {
+		return group
+	}
+	return ""
+}
+
+// Resource returns the fully qualified kubectl reference, e.g.
+// deployment.v1.apps/api, so kinds that share a name across groups stay apart
+func (o Object) Resource() string {
+	kind := strings.ToLower(o.Kind)
+	if group, version, ok := strings.Cut(o.APIVersion, "/"); ok {
+		kind += "." + version + "." + group
+	}
+	return kind + "/" + o.Name
+}
+
+// DeleteArgs returns the kubectl arguments deleting the object
+func (o Object) DeleteArgs() []string {
+	args := []string{"delete", o.Resource(), "--ignore-not-found", "--wait=false"}
+	if o.Namespace != "" {
+		args = append(args, "-n", o.Namespace)
+	}
+	return args
+}
+
+// Label sets PlanLabel to planID on every object in a multi-document
+// manifest and returns the labelled manifest with the objects it holds.
+// Objects without a namespace get defaultNamespace in the returned list;
+// the manifest itself is left to kubectl's -n.
+func Label(manifest, planID, defaultNamespace string) (string, []Object, error) {
+	var docs []string
+	var objects []Object
+	for _, doc := range strings.Split(manifest, "\n---") {
+		doc = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(doc), "---"))
+		if doc == "" {
+			continue
+		}
+		var obj map[string]any
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return "", nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if obj == nil {
+			continue
+		}
+		if items, ok := obj["items"].([]any); ok && strings.HasSuffix(fmt.Sprint(obj["kind"]), "List") {
+			for _, item := range items {
+				if m, ok := item.(map[string]any); ok {
+					objects = append(objects, labelObject(m, planID, defaultNamespace))
+				}
+			}
+		} else {
+			objects = append(objects, labelObject(obj, planID, defaultNamespace))
+		}
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to render manifest: %w", err)
+		}
+		docs = append(docs, strings.TrimSpace(string(data)))
+	}
+	if len(docs) == 0 {
+		return manifest, nil, nil
+	}
+	return strings.Join(docs, "\n---\n") + "\n", objects, nil
+}
+
+func labelObject(obj map[string]any, planID, defaultNamespace string) Object {
+	meta, _ := obj["metadata"].(map[string]any)
+	if meta == nil {
+		meta = map[string]any{}
+		obj["metadata"] = meta
+	}
+	labels, _ := meta["labels"].(map[string]any)
+	if labels == nil {
+		labels = map[string]any{}
+		meta["labels"] = labels
+	}
+	labels[PlanLabel] = planID
+
+	o := Object{
+		APIVersion: fmt.Sprint(obj["apiVersion"]),
+		Kind:       fmt.Sprint(obj["kind"]),
+		Namespace:  defaultNamespace,
+	}
+	if name, ok := meta["name"].(string); ok {
+		o.Name = name
+	}
+	if namespace, ok := meta["namespace"].(string); ok && namespace != "" {
+		o.Namespace = namespace
+	}
+	return o
+}
+
+// Find lists the objects in the cluster labelled with planID, across every
+// namespace and every resource type that can be listed and deleted. Types
+// the caller may not list are skipped and reported in warnings.
+func Find(ctx context.Context, client K8sClient, planID string) (objects []Object, warnings []string, err error) {
+	out, err := client.Run(ctx, "api-resources", "--verbs=list,delete", "-o", "name")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list resource types: %w", err)
+	}
+	var resources []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		// Events are never applied by plans and listing them is slow
+		if line == "" || line == "events" || strings.HasPrefix(line, "events.") {
+			continue
+		}
+		resources = append(resources, line)
+	}
+	if len(resources) == 0 {
+		return nil, nil, nil
+	}
+
+	selector := PlanLabel + "=" + planID
+	if objects, err := list(ctx, client, strings.Join(resources, ","), selector); err == nil {
+		return objects, nil, nil
+	}
+	// One type the caller may not list fails the combined get, so retry
+	// them one by one
+	for _, resource := range resources {
+		found, err := list(ctx, client, resource, selector)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipped %s: %v", resource, err))
+			continue
+		}
+		objects = append(objects, found...)
+	}
+	return objects, warnings, nil
+}
+
+func list(ctx context.Context, client K8sClient, resources, selector string) ([]Object, error) {
+	out, err := client.Run(ctx, "get", resources, "-A", "-l", selector, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Items []struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+			Metadata   struct {
+				Name            string            `json:"name"`
+				Namespace       string            `json:"namespace"`
+				OwnerReferences []json.RawMessage `json:"ownerReferences"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	var objects []Object
+	for _, item := range result.Items {
+		// Owned objects copied the label from their owner and go with it
+		if len(item.Metadata.OwnerReferences) > 0 {
+			continue
+		}
+		objects = append(objects, Object{
+			APIVersion: item.APIVersion,
+			Kind:       item.Kind,
+			Namespace:  item.Metadata.Namespace,
+			Name:       item.Metadata.Name,
+		})
+	}
+	return objects, nil
+}
+
+// Orphans returns the live objects that are not among the applied ones. An
+// applied object without a namespace matches the name in any namespace,
+// since kubectl placed it in the context's default, and cluster-scoped live
+// objects ignore the namespace the manifest was applied with.
+func Orphans(live, applied []Object) []Object {
+	var orphans []Object
+	for _, o := range live {
+		kept := false
+		for _, a := range applied {
+			if strings.EqualFold(a.Kind, o.Kind) && a.group() == o.group() && a.Name == o.Name &&
+				(a.Namespace == "" || o.Namespace == "" || a.Namespace == o.Namespace) {
+				kept = true
+				break
+			}
+		}
+		if !kept {
+			orphans = append(orphans, o)
+		}
+	}
+	return orphans
+}
+
+// DeleteOrder sorts objects for deletion: namespaced objects first, then
+// cluster-scoped ones, with namespaces last so their contents go through
+// their own deletes
+func DeleteOrder(objects []Object) []Object {
+	rank := func(o Object) int {
+		switch {
+		case o.Kind == "Namespace" && o.APIVersion == "v1":
+			return 2
+		case o.Namespace == "":
+			return 1
+		}
+		return 0
+	}
+	sorted := append([]Object(nil), objects...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if ri, rj := rank(sorted[i]), rank(sorted[j]); ri != rj {
+			return ri < rj
+		}
+		return sorted[i].String() < sorted[j].String()
+	})
+	return sorted
+}
@@ -0,0 +1,140 @@
+package prune
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLabel(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: Namespace
+metadata:
+  name: shop
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: shop
+  labels:
+    app: api
+spec:
+  replicas: 2
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: api
+`
+	labelled, objects, err := Label(manifest, "20261015-093000-3f9a", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(labelled, PlanLabel+": 20261015-093000-3f9a"); got != 3 {
+		t.Errorf("labelled %d objects:\n%s", got, labelled)
+	}
+	if !strings.Contains(labelled, "app: api") || !strings.Contains(labelled, "replicas: 2") {
+		t.Errorf("existing fields lost:\n%s", labelled)
+	}
+	want := []Object{
+		{APIVersion: "v1", Kind: "Namespace", Namespace: "default", Name: "shop"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "shop", Name: "api"},
+		{APIVersion: "v1", Kind: "Service", Namespace: "default", Name: "api"},
+	}
+	if !reflect.DeepEqual(objects, want) {
+		t.Errorf("objects = %+v", objects)
+	}
+
+	if _, _, err := Label("kind: [", "p", ""); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestObjectArgs(t *testing.T) {
+	deploy := Object{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "shop", Name: "api"}
+	if got := strings.Join(deploy.DeleteArgs(), " "); got != "delete deployment.v1.apps/api --ignore-not-found --wait=false -n shop" {
+		t.Errorf("args = %s", got)
+	}
+	ns := Object{APIVersion: "v1", Kind: "Namespace", Name: "shop"}
+	if got := strings.Join(ns.DeleteArgs(), " "); got != "delete namespace/shop --ignore-not-found --wait=false" {
+		t.Errorf("args = %s", got)
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	live := []Object{
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "shop", Name: "api"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "shop", Name: "worker"},
+		{APIVersion: "v1", Kind: "Service", Namespace: "shop", Name: "api"},
+		{APIVersion: "networking.k8s.io/v1", Kind: "Ingress", Namespace: "shop", Name: "api"},
+		{APIVersion: "v1", Kind: "Namespace", Name: "shop"},
+	}
+	applied := []Object{
+		{APIVersion: "v1", Kind: "Namespace", Namespace: "default", Name: "shop"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "shop", Name: "api"},
+		{APIVersion: "v1", Kind: "Service", Name: "api"},
+		{APIVersion: "extensions/v1beta1", Kind: "Ingress", Namespace: "shop", Name: "api"},
+	}
+	got := Orphans(live, applied)
+	want := []Object{live[1], live[3]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orphans = %+v", got)
+	}
+}
+
+func TestDeleteOrder(t *testing.T) {
+	objects := []Object{
+		{APIVersion: "v1", Kind: "Namespace", Name: "shop"},
+		{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "reader"},
+		{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "shop", Name: "api"},
+	}
+	var got []string
+	for _, o := range DeleteOrder(objects) {
+		got = append(got, o.String())
+	}
+	if want := "shop/deployment/api clusterrole/reader namespace/shop"; strings.Join(got, " ") != want {
+		t.Errorf("order = %v", got)
+	}
+}
+
+// mockK8sClient answers Run by its joined args
+type mockK8sClient struct {
+	outputs map[string]string
+}
+
+func (m *mockK8sClient) Run(ctx context.Context, args ...string) (string, error) {
+	if out, ok := m.outputs[strings.Join(args, " ")]; ok {
+		return out, nil
+	}
+	return "", errors.New("forbidden")
+}
+
+func TestFind(t *testing.T) {
+	client := &mockK8sClient{outputs: map[string]string{
+		"api-resources --verbs=list,delete -o name": "deployments.apps\nevents\nsecrets\nservices\n",
+		"get deployments.apps -A -l clanker/plan-id=p1 -o json": `{"items": [
+			{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "api", "namespace": "shop"}}
+		]}`,
+		"get services -A -l clanker/plan-id=p1 -o json": `{"items": [
+			{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "api", "namespace": "shop"}},
+			{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "api-x", "namespace": "shop", "ownerReferences": [{"kind": "Deployment"}]}}
+		]}`,
+	}}
+
+	objects, warnings, err := Find(context.Background(), client, "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[0].Name != "api" || objects[1].Kind != "Service" {
+		t.Errorf("objects = %+v", objects)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "secrets") {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
@@ -0,0 +1,275 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// rbacObjectList is the subset of kubectl get -o json used for roles and
+// bindings
+type rbacObjectList struct {
+	Items []struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Rules    []PolicyRule `json:"rules"`
+		RoleRef  RoleRef      `json:"roleRef"`
+		Subjects []Subject    `json:"subjects"`
+	} `json:"items"`
+}
+
+// LoadInventory enumerates roles, cluster roles, and both binding kinds
+func LoadInventory(ctx context.Context, client K8sClient) (*Inventory, error) {
+	inv := &Inventory{}
+	for _, source := range []struct {
+		args    []string
+		kind    string
+		binding bool
+	}{
+		{args: []string{"get", "clusterroles"}, kind: "ClusterRole"},
+		{args: []string{"get", "roles", "-A"}, kind: "Role"},
+		{args: []string{"get", "clusterrolebindings"}, kind: "ClusterRoleBinding", binding: true},
+		{args: []string{"get", "rolebindings", "-A"}, kind: "RoleBinding", binding: true},
+	} {
+		data, err := client.RunJSON(ctx, source.args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %ss: %w", strings.ToLower(source.kind), err)
+		}
+		var list rbacObjectList
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("failed to parse %ss: %w", strings.ToLower(source.kind), err)
+		}
+		for _, item := range list.Items {
+			if source.binding {
+				inv.Bindings = append(inv.Bindings, Binding{
+					Kind:      source.kind,
+					Name:      item.Metadata.Name,
+					Namespace: item.Metadata.Namespace,
+					RoleRef:   item.RoleRef,
+					Subjects:  item.Subjects,
+				})
+				continue
+			}
+			inv.Roles = append(inv.Roles, Role{
+				Kind:      source.kind,
+				Name:      item.Metadata.Name,
+				Namespace: item.Metadata.Namespace,
+				Rules:     item.Rules,
+			})
+		}
+	}
+	return inv, nil
+}
+
+// findRole resolves a binding's roleRef. RoleBindings may reference a Role in
+// their own namespace or a ClusterRole.
+func (inv *Inventory) findRole(b Binding) *Role {
+	for i := range inv.Roles {
+		r := &inv.Roles[i]
+		if r.Kind != b.RoleRef.Kind || r.Name != b.RoleRef.Name {
+			continue
+		}
+		if r.Kind == "Role" && r.Namespace != b.Namespace {
+			continue
+		}
+		return r
+	}
+	return nil
+}
+
+// WhoCan returns every subject allowed to perform verb on resource in the
+// given namespace ("" means cluster-wide, so only ClusterRoleBindings count).
+func WhoCan(inv *Inventory, verb, resource, namespace string) []AccessGrant {
+	var grants []AccessGrant
+	for _, b := range inv.Bindings {
+		if b.Kind == "RoleBinding" && (namespace == "" || b.Namespace != namespace) {
+			continue
+		}
+		role := inv.findRole(b)
+		if role == nil || !roleAllows(role, verb, resource) {
+			continue
+		}
+		for _, subject := range b.Subjects {
+			grants = append(grants, AccessGrant{
+				Subject:   subject,
+				Binding:   b.Kind + "/" + b.Name,
+				Role:      role.Kind + "/" + role.Name,
+				Namespace: b.Namespace,
+			})
+		}
+	}
+	sort.SliceStable(grants, func(i, j int) bool {
+		return subjectString(grants[i].Subject) < subjectString(grants[j].Subject)
+	})
+	return grants
+}
+
+// SubjectBindings returns the bindings that name the subject. Service
+// accounts also inherit bindings to their groups.
+func SubjectBindings(inv *Inventory, subject Subject) []Binding {
+	var out []Binding
+	for _, b := range inv.Bindings {
+		for _, s := range b.Subjects {
+			if subjectMatches(s, subject) {
+				out = append(out, b)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func subjectMatches(bound, subject Subject) bool {
+	if bound.Kind == subject.Kind && bound.Name == subject.Name {
+		return subject.Kind != "ServiceAccount" || bound.Namespace == subject.Namespace
+	}
+	if subject.Kind == "ServiceAccount" && bound.Kind == "Group" {
+		switch bound.Name {
+		case "system:serviceaccounts", "system:serviceaccounts:" + subject.Namespace, "system:authenticated":
+			return true
+		}
+	}
+	return false
+}
+
+func roleAllows(role *Role, verb, resource string) bool {
+	for _, rule := range role.Rules {
+		if len(rule.ResourceNames) > 0 {
+			continue // scoped to specific objects, not the resource type
+		}
+		if containsOrWildcard(rule.Verbs, verb) && containsOrWildcard(rule.Resources, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsOrWildcard(values []string, want string) bool {
+	for _, v := range values {
+		if v == "*" || v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// broadSubjects are groups that cover every (or every authenticated) caller
+var broadSubjects = map[string]string{
+	"system:anonymous":       "every anonymous caller",
+	"system:unauthenticated": "every unauthenticated caller",
+	"system:authenticated":   "every authenticated identity",
+	"system:serviceaccounts": "every service account in the cluster",
+}
+
+// AnalyzeBindings flags over-broad bindings: cluster-admin grants, wildcard
+// verbs or resources, secrets access, and bindings to catch-all groups.
+// Built-in system: bindings are skipped.
+func AnalyzeBindings(inv *Inventory) []Finding {
+	var findings []Finding
+	for _, b := range inv.Bindings {
+		if strings.HasPrefix(b.Name, "system:") || strings.HasPrefix(b.RoleRef.Name, "system:") {
+			continue
+		}
+		ref := b.Kind + "/" + b.Name
+		if b.Namespace != "" {
+			ref = b.Namespace + "/" + ref
+		}
+		role := inv.findRole(b)
+
+		for _, subject := range b.Subjects {
+			who := subjectString(subject)
+			if desc, ok := broadSubjects[subject.Name]; ok && subject.Kind == "Group" {
+				findings = append(findings, Finding{Severity: SeverityCritical, Binding: ref, Subject: who,
+					Reason: fmt.Sprintf("grants %s/%s to %s", b.RoleRef.Kind, b.RoleRef.Name, desc)})
+			}
+			if subject.Kind == "ServiceAccount" && subject.Name == "default" && b.RoleRef.Name == "cluster-admin" {
+				findings = append(findings, Finding{Severity: SeverityCritical, Binding: ref, Subject: who,
+					Reason: "default service account is cluster-admin; every pod in the namespace inherits it"})
+			}
+		}
+
+		switch {
+		case b.RoleRef.Name == "cluster-admin" && b.Kind == "ClusterRoleBinding":
+			findings = append(findings, Finding{Severity: SeverityCritical, Binding: ref, Subject: subjectsString(b.Subjects),
+				Reason: "cluster-admin across the whole cluster"})
+		case b.RoleRef.Name == "cluster-admin":
+			findings = append(findings, Finding{Severity: SeverityHigh, Binding: ref, Subject: subjectsString(b.Subjects),
+				Reason: fmt.Sprintf("cluster-admin within namespace %s", b.Namespace)})
+		case role != nil:
+			if reason := wildcardReason(role); reason != "" {
+				severity := SeverityHigh
+				if b.Kind == "RoleBinding" {
+					severity = SeverityMedium
+				}
+				findings = append(findings, Finding{Severity: severity, Binding: ref, Subject: subjectsString(b.Subjects),
+					Reason: fmt.Sprintf("%s/%s %s", role.Kind, role.Name, reason)})
+			}
+		}
+	}
+
+	rank := map[Severity]int{SeverityCritical: 0, SeverityHigh: 1, SeverityMedium: 2}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return rank[findings[i].Severity] < rank[findings[j].Severity]
+	})
+	return findings
+}
+
+// wildcardReason describes wildcard or secrets-wide rules in a role
+func wildcardReason(role *Role) string {
+	var reasons []string
+	for _, rule := range role.Rules {
+		switch {
+		case containsString(rule.Verbs, "*") && containsString(rule.Resources, "*"):
+			reasons = append(reasons, "allows every verb on every resource")
+		case containsString(rule.Verbs, "*"):
+			reasons = append(reasons, fmt.Sprintf("allows every verb on %s", strings.Join(rule.Resources, ",")))
+		case containsString(rule.Resources, "*"):
+			reasons = append(reasons, fmt.Sprintf("allows %s on every resource", strings.Join(rule.Verbs, ",")))
+		case containsString(rule.Resources, "secrets") && len(rule.ResourceNames) == 0 &&
+			(containsString(rule.Verbs, "get") || containsString(rule.Verbs, "list")):
+			reasons = append(reasons, "can read every secret")
+		}
+	}
+	return strings.Join(dedupe(reasons), "; ")
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(values []string) []string {
+	seen := map[string]bool{}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func subjectString(s Subject) string {
+	if s.Kind == "ServiceAccount" {
+		return fmt.Sprintf("ServiceAccount %s/%s", s.Namespace, s.Name)
+	}
+	return fmt.Sprintf("%s %s", s.Kind, s.Name)
+}
+
+func subjectsString(subjects []Subject) string {
+	parts := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		parts = append(parts, subjectString(s))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,366 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/log"
+)
+
+var logger = log.New("k8s.rbac")
+
+// SubAgent handles RBAC and permission queries
+type SubAgent struct {
+	client K8sClient
+	debug  bool
+}
+
+// NewSubAgent creates a new rbac sub-agent
+func NewSubAgent(client K8sClient, debug bool) *SubAgent {
+	return &SubAgent{
+		client: client,
+		debug:  debug,
+	}
+}
+
+// HandleQuery processes an RBAC query and returns the result
+func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
+	logger.Debugf("handling query: %s", query)
+
+	analysis := s.analyzeQuery(query)
+	if analysis.Namespace == "" {
+		analysis.Namespace = opts.Namespace
+	}
+
+	logger.Debugf("analysis: type=%s, subject=%+v, verb=%s, resource=%s, namespace=%s", analysis.Type, analysis.Subject, analysis.Verb, analysis.Resource, analysis.Namespace)
+
+	switch analysis.Type {
+	case QuerySubjectAccess:
+		return s.handleSubjectAccess(ctx, analysis)
+	case QueryWhoCan:
+		return s.handleWhoCan(ctx, analysis)
+	case QueryAudit:
+		return s.handleAudit(ctx)
+	default:
+		return s.handleList(ctx, analysis)
+	}
+}
+
+// queryAnalysis contains parsed query information
+type queryAnalysis struct {
+	Type      QueryType
+	Subject   Subject // empty Kind means the caller's own identity
+	Verb      string
+	Resource  string
+	Namespace string
+}
+
+var (
+	serviceAccountPattern = regexp.MustCompile(`(?i)\b(?:service\s*account|sa)\s+([a-z0-9][a-z0-9.-]*)(?:/([a-z0-9][a-z0-9.-]*))?`)
+	userPattern           = regexp.MustCompile(`(?i)\buser\s+([\w@.:-]+)`)
+	groupPattern          = regexp.MustCompile(`(?i)\bgroup\s+([\w@.:-]+)`)
+	whoCanPattern         = regexp.MustCompile(`(?i)\bwho\s+(?:can|is allowed to|has (?:permission|access) to)\s+([a-z-]+)\s+(?:the\s+|into\s+)?([a-z][a-z0-9./-]*)`)
+	namespacePattern      = regexp.MustCompile(`(?i)\b(?:in|within)\s+(?:the\s+)?(?:namespace\s+|ns\s+)?([a-z0-9][a-z0-9-]*)`)
+	selfPattern           = regexp.MustCompile(`(?i)\bwhat\s+can\s+i\s+do\b|\bmy\s+permissions\b`)
+)
+
+// analyzeQuery parses the query to determine the question and its subject
+func (s *SubAgent) analyzeQuery(query string) queryAnalysis {
+	q := strings.ToLower(query)
+	analysis := queryAnalysis{Type: QueryList}
+
+	if m := namespacePattern.FindStringSubmatch(q); m != nil && !isClusterWord(m[1]) {
+		analysis.Namespace = m[1]
+	}
+
+	if m := whoCanPattern.FindStringSubmatch(q); m != nil {
+		analysis.Type = QueryWhoCan
+		analysis.Verb, analysis.Resource = normalizeVerbResource(m[1], m[2])
+		return analysis
+	}
+
+	if containsAny(q, []string{"audit", "over-broad", "overbroad", "too broad", "overly broad", "overly permissive",
+		"excessive", "risky", "wildcard", "cluster-admin"}) {
+		analysis.Type = QueryAudit
+		return analysis
+	}
+
+	switch {
+	case serviceAccountPattern.MatchString(query):
+		m := serviceAccountPattern.FindStringSubmatch(query)
+		analysis.Subject = Subject{Kind: "ServiceAccount", Name: m[1]}
+		if m[2] != "" {
+			analysis.Subject = Subject{Kind: "ServiceAccount", Namespace: m[1], Name: m[2]}
+			analysis.Namespace = m[1]
+		}
+		analysis.Type = QuerySubjectAccess
+	case userPattern.MatchString(query):
+		analysis.Subject = Subject{Kind: "User", Name: userPattern.FindStringSubmatch(query)[1]}
+		analysis.Type = QuerySubjectAccess
+	case groupPattern.MatchString(query):
+		analysis.Subject = Subject{Kind: "Group", Name: groupPattern.FindStringSubmatch(query)[1]}
+		analysis.Type = QuerySubjectAccess
+	case selfPattern.MatchString(q):
+		analysis.Type = QuerySubjectAccess
+	}
+
+	return analysis
+}
+
+// handleSubjectAccess answers "what can X do" with kubectl auth can-i --list,
+// followed by the bindings that grant the access
+func (s *SubAgent) handleSubjectAccess(ctx context.Context, analysis queryAnalysis) (*Response, error) {
+	subject := analysis.Subject
+	if subject.Kind == "ServiceAccount" && subject.Namespace == "" {
+		subject.Namespace = analysis.Namespace
+		if subject.Namespace == "" {
+			subject.Namespace = "default"
+		}
+	}
+	namespace := analysis.Namespace
+	if subject.Kind == "ServiceAccount" && namespace == "" {
+		namespace = subject.Namespace
+	}
+
+	var sb strings.Builder
+	who := "you"
+	if subject.Kind != "" {
+		who = subjectString(subject)
+	}
+	scope := "namespace " + namespace
+	if namespace == "" {
+		scope = "the current namespace"
+	}
+	sb.WriteString(fmt.Sprintf("Permissions for %s in %s\n\n", who, scope))
+
+	// Impersonating a group needs a user as well, so groups only get the
+	// binding view below
+	if subject.Kind != "Group" {
+		args := []string{"auth", "can-i", "--list"}
+		if as := impersonationName(subject); as != "" {
+			args = append(args, "--as", as)
+		}
+		output, err := s.client.RunWithNamespace(ctx, namespace, args...)
+		if err != nil {
+			sb.WriteString(fmt.Sprintf("kubectl auth can-i --list failed: %v\n", err))
+			sb.WriteString("(impersonation requires the 'impersonate' verb; falling back to binding analysis)\n\n")
+		} else {
+			sb.WriteString(strings.TrimRight(output, "\n"))
+			sb.WriteString("\n\n")
+		}
+	}
+
+	if subject.Kind == "" {
+		return &Response{Type: ResponseTypeResult, Data: sb.String(), Message: "Permissions for current identity"}, nil
+	}
+
+	inv, err := LoadInventory(ctx, s.client)
+	if err != nil {
+		sb.WriteString(fmt.Sprintf("Could not enumerate bindings: %v\n", err))
+		return &Response{Type: ResponseTypeResult, Data: sb.String()}, nil
+	}
+	bindings := SubjectBindings(inv, subject)
+	if len(bindings) == 0 {
+		sb.WriteString("No role bindings reference this subject directly.\n")
+	} else {
+		sb.WriteString("Granted by:\n")
+		for _, b := range bindings {
+			sb.WriteString(fmt.Sprintf("  %s -> %s/%s", bindingRef(b), b.RoleRef.Kind, b.RoleRef.Name))
+			if role := inv.findRole(b); role != nil {
+				if reason := wildcardReason(role); reason != "" {
+					sb.WriteString("  [" + reason + "]")
+				}
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	return &Response{
+		Type:    ResponseTypeResult,
+		Data:    sb.String(),
+		Message: fmt.Sprintf("%d binding(s) grant access to %s", len(bindings), who),
+	}, nil
+}
+
+// handleWhoCan answers "who can <verb> <resource> in <namespace>"
+func (s *SubAgent) handleWhoCan(ctx context.Context, analysis queryAnalysis) (*Response, error) {
+	inv, err := LoadInventory(ctx, s.client)
+	if err != nil {
+		return &Response{
+			Type:    ResponseTypeError,
+			Message: fmt.Sprintf("Failed to enumerate RBAC: %v", err),
+			Error:   err,
+		}, nil
+	}
+
+	grants := WhoCan(inv, analysis.Verb, analysis.Resource, analysis.Namespace)
+
+	var sb strings.Builder
+	scope := "cluster-wide"
+	if analysis.Namespace != "" {
+		scope = "in namespace " + analysis.Namespace
+	}
+	sb.WriteString(fmt.Sprintf("Subjects that can %s %s %s\n\n", analysis.Verb, analysis.Resource, scope))
+	if len(grants) == 0 {
+		sb.WriteString("No bindings grant this permission.\n")
+	}
+	for _, g := range grants {
+		sb.WriteString(fmt.Sprintf("  %-50s via %s -> %s\n", subjectString(g.Subject), g.Binding, g.Role))
+	}
+
+	return &Response{
+		Type:    ResponseTypeResult,
+		Data:    sb.String(),
+		Message: fmt.Sprintf("%d subject(s) can %s %s", len(grants), analysis.Verb, analysis.Resource),
+	}, nil
+}
+
+// handleAudit reports over-broad bindings
+func (s *SubAgent) handleAudit(ctx context.Context) (*Response, error) {
+	inv, err := LoadInventory(ctx, s.client)
+	if err != nil {
+		return &Response{
+			Type:    ResponseTypeError,
+			Message: fmt.Sprintf("Failed to enumerate RBAC: %v", err),
+			Error:   err,
+		}, nil
+	}
+
+	findings := AnalyzeBindings(inv)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("RBAC Audit (%d roles, %d bindings)\n\n", len(inv.Roles), len(inv.Bindings)))
+	if len(findings) == 0 {
+		sb.WriteString("No over-broad bindings found outside the built-in system: roles.\n")
+	}
+	for _, f := range findings {
+		sb.WriteString(fmt.Sprintf("[%s] %s\n", strings.ToUpper(string(f.Severity)), f.Binding))
+		if f.Subject != "" {
+			sb.WriteString(fmt.Sprintf("  subjects: %s\n", f.Subject))
+		}
+		sb.WriteString(fmt.Sprintf("  %s\n", f.Reason))
+	}
+
+	return &Response{
+		Type:    ResponseTypeResult,
+		Data:    sb.String(),
+		Message: fmt.Sprintf("%d RBAC finding(s)", len(findings)),
+	}, nil
+}
+
+// handleList lists roles and bindings in a namespace, or cluster-wide
+func (s *SubAgent) handleList(ctx context.Context, analysis queryAnalysis) (*Response, error) {
+	var args []string
+	if analysis.Namespace != "" {
+		args = []string{"get", "roles,rolebindings", "-o", "wide"}
+	} else {
+		args = []string{"get", "clusterroles,clusterrolebindings,roles,rolebindings", "-A", "-o", "wide"}
+	}
+	output, err := s.client.RunWithNamespace(ctx, analysis.Namespace, args...)
+	if err != nil {
+		return &Response{
+			Type:    ResponseTypeError,
+			Message: fmt.Sprintf("Failed to list RBAC resources: %v", err),
+			Error:   err,
+		}, nil
+	}
+	return &Response{Type: ResponseTypeResult, Data: output}, nil
+}
+
+// impersonationName renders a subject as a kubectl --as value
+func impersonationName(subject Subject) string {
+	switch subject.Kind {
+	case "ServiceAccount":
+		return fmt.Sprintf("system:serviceaccount:%s:%s", subject.Namespace, subject.Name)
+	case "User":
+		return subject.Name
+	}
+	return ""
+}
+
+func bindingRef(b Binding) string {
+	if b.Namespace != "" {
+		return fmt.Sprintf("%s/%s/%s", b.Namespace, b.Kind, b.Name)
+	}
+	return b.Kind + "/" + b.Name
+}
+
+// verbAliases maps everyday wording onto Kubernetes API verbs
+var verbAliases = map[string]string{
+	"read":   "get",
+	"view":   "get",
+	"see":    "get",
+	"edit":   "update",
+	"modify": "update",
+	"change": "update",
+	"remove": "delete",
+	"make":   "create",
+}
+
+// resourceAliases maps short names onto plural resource names
+var resourceAliases = map[string]string{
+	"po":     "pods",
+	"deploy": "deployments",
+	"svc":    "services",
+	"cm":     "configmaps",
+	"ns":     "namespaces",
+	"no":     "nodes",
+	"pvc":    "persistentvolumeclaims",
+	"pv":     "persistentvolumes",
+	"sa":     "serviceaccounts",
+	"ing":    "ingresses",
+	"ds":     "daemonsets",
+	"sts":    "statefulsets",
+}
+
+// normalizeVerbResource turns "exec into pod" or "read secret" into the
+// verb and resource RBAC rules are written against
+func normalizeVerbResource(verb, resource string) (string, string) {
+	verb = strings.ToLower(verb)
+	resource = strings.ToLower(strings.TrimRight(resource, "?.,"))
+
+	if alias, ok := resourceAliases[resource]; ok {
+		resource = alias
+	} else if !strings.HasSuffix(resource, "s") {
+		switch {
+		case strings.HasSuffix(resource, "y"):
+			resource = strings.TrimSuffix(resource, "y") + "ies"
+		case strings.HasSuffix(resource, "ss"):
+			resource += "es"
+		default:
+			resource += "s"
+		}
+	} else if strings.HasSuffix(resource, "ss") {
+		resource += "es" // ingress
+	}
+
+	switch verb {
+	case "exec":
+		return "create", resource + "/exec"
+	case "port-forward":
+		return "create", resource + "/portforward"
+	}
+	if alias, ok := verbAliases[verb]; ok {
+		verb = alias
+	}
+	return verb, resource
+}
+
+func isClusterWord(word string) bool {
+	switch word {
+	case "cluster", "all", "any", "every", "my":
+		return true
+	}
+	return false
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package rbac
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// mockK8sClient implements K8sClient interface for testing. RunJSON answers
+// from jsonByResource keyed on the resource argument.
+type mockK8sClient struct {
+	runWithNamespace    string
+	runWithNamespaceErr error
+	lastArgs            []string
+	jsonByResource      map[string]string
+}
+
+func (m *mockK8sClient) Run(ctx context.Context, args ...string) (string, error) {
+	return m.runWithNamespace, m.runWithNamespaceErr
+}
+
+func (m *mockK8sClient) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	m.lastArgs = args
+	return m.runWithNamespace, m.runWithNamespaceErr
+}
+
+func (m *mockK8sClient) RunJSON(ctx context.Context, args ...string) ([]byte, error) {
+	if out, ok := m.jsonByResource[args[1]]; ok {
+		return []byte(out), nil
+	}
+	return []byte(`{"items":[]}`), nil
+}
+
+func testClient() *mockK8sClient {
+	return &mockK8sClient{jsonByResource: map[string]string{
+		"clusterroles": `{"items":[
+			{"metadata":{"name":"cluster-admin"},"rules":[{"apiGroups":["*"],"resources":["*"],"verbs":["*"]}]},
+			{"metadata":{"name":"pod-reader"},"rules":[{"apiGroups":[""],"resources":["pods"],"verbs":["get","list"]}]}
+		]}`,
+		"roles": `{"items":[
+			{"metadata":{"name":"deployer","namespace":"prod"},"rules":[{"apiGroups":[""],"resources":["pods"],"verbs":["*"]}]},
+			{"metadata":{"name":"one-secret","namespace":"prod"},"rules":[{"resources":["secrets"],"resourceNames":["tls"],"verbs":["get"]}]}
+		]}`,
+		"clusterrolebindings": `{"items":[
+			{"metadata":{"name":"ops-admin"},"roleRef":{"kind":"ClusterRole","name":"cluster-admin"},"subjects":[{"kind":"Group","name":"ops"}]},
+			{"metadata":{"name":"system:masters"},"roleRef":{"kind":"ClusterRole","name":"cluster-admin"},"subjects":[{"kind":"Group","name":"system:masters"}]},
+			{"metadata":{"name":"everyone-reads"},"roleRef":{"kind":"ClusterRole","name":"pod-reader"},"subjects":[{"kind":"Group","name":"system:authenticated"}]}
+		]}`,
+		"rolebindings": `{"items":[
+			{"metadata":{"name":"ci-deployer","namespace":"prod"},"roleRef":{"kind":"Role","name":"deployer"},"subjects":[{"kind":"ServiceAccount","name":"ci","namespace":"build"}]},
+			{"metadata":{"name":"staging-deployer","namespace":"staging"},"roleRef":{"kind":"Role","name":"deployer"},"subjects":[{"kind":"User","name":"bob"}]}
+		]}`,
+	}}
+}
+
+func TestAnalyzeQuery(t *testing.T) {
+	s := NewSubAgent(&mockK8sClient{}, false)
+
+	tests := []struct {
+		query     string
+		queryType QueryType
+		subject   Subject
+		verb      string
+		resource  string
+		namespace string
+	}{
+		{query: "what can serviceaccount builder do in ci", queryType: QuerySubjectAccess,
+			subject: Subject{Kind: "ServiceAccount", Name: "builder"}, namespace: "ci"},
+		{query: "what can service account build/ci do", queryType: QuerySubjectAccess,
+			subject: Subject{Kind: "ServiceAccount", Namespace: "build", Name: "ci"}, namespace: "build"},
+		{query: "what can user alice@example.com do", queryType: QuerySubjectAccess,
+			subject: Subject{Kind: "User", Name: "alice@example.com"}},
+		{query: "who can delete pods in prod", queryType: QueryWhoCan, verb: "delete", resource: "pods", namespace: "prod"},
+		{query: "who can read secrets in the cluster", queryType: QueryWhoCan, verb: "get", resource: "secrets"},
+		{query: "who can exec into pod in payments", queryType: QueryWhoCan, verb: "create", resource: "pods/exec", namespace: "payments"},
+		{query: "who can list ingress", queryType: QueryWhoCan, verb: "list", resource: "ingresses"},
+		{query: "audit rbac for over-broad bindings", queryType: QueryAudit},
+		{query: "list role bindings in prod", queryType: QueryList, namespace: "prod"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := s.analyzeQuery(tt.query)
+			if got.Type != tt.queryType || got.Subject != tt.subject || got.Verb != tt.verb ||
+				got.Resource != tt.resource || got.Namespace != tt.namespace {
+				t.Errorf("analyzeQuery(%q) = %+v", tt.query, got)
+			}
+		})
+	}
+}
+
+func TestWhoCan(t *testing.T) {
+	inv, err := LoadInventory(context.Background(), testClient())
+	if err != nil {
+		t.Fatalf("LoadInventory: %v", err)
+	}
+
+	grants := WhoCan(inv, "delete", "pods", "prod")
+	var subjects []string
+	for _, g := range grants {
+		subjects = append(subjects, subjectString(g.Subject))
+	}
+	got := strings.Join(subjects, "; ")
+	if !strings.Contains(got, "Group ops") || !strings.Contains(got, "ServiceAccount build/ci") {
+		t.Errorf("WhoCan delete pods in prod = %s", got)
+	}
+	// bob's binding is in staging, and pod-reader cannot delete
+	if strings.Contains(got, "bob") || strings.Contains(got, "system:authenticated") {
+		t.Errorf("WhoCan included unrelated subjects: %s", got)
+	}
+
+	// Cluster-wide questions ignore namespaced bindings
+	for _, g := range WhoCan(inv, "list", "pods", "") {
+		if g.Namespace != "" {
+			t.Errorf("cluster-wide WhoCan returned namespaced grant %+v", g)
+		}
+	}
+
+	// resourceNames-scoped rules do not grant the whole resource type
+	for _, g := range WhoCan(inv, "get", "secrets", "prod") {
+		if g.Role == "Role/one-secret" {
+			t.Errorf("resourceNames rule counted as full access: %+v", g)
+		}
+	}
+}
+
+func TestAnalyzeBindings(t *testing.T) {
+	inv, err := LoadInventory(context.Background(), testClient())
+	if err != nil {
+		t.Fatalf("LoadInventory: %v", err)
+	}
+
+	findings := AnalyzeBindings(inv)
+	byBinding := map[string]Finding{}
+	for _, f := range findings {
+		byBinding[f.Binding] = f
+	}
+
+	if f, ok := byBinding["ClusterRoleBinding/ops-admin"]; !ok || f.Severity != SeverityCritical {
+		t.Errorf("expected critical cluster-admin finding, got %+v", findings)
+	}
+	if f, ok := byBinding["ClusterRoleBinding/everyone-reads"]; !ok || !strings.Contains(f.Reason, "every authenticated") {
+		t.Errorf("expected system:authenticated finding, got %+v", findings)
+	}
+	if f, ok := byBinding["prod/RoleBinding/ci-deployer"]; !ok || f.Severity != SeverityMedium {
+		t.Errorf("expected medium wildcard-verb finding, got %+v", findings)
+	}
+	if _, ok := byBinding["ClusterRoleBinding/system:masters"]; ok {
+		t.Error("built-in system: bindings should be skipped")
+	}
+	if findings[0].Severity != SeverityCritical {
+		t.Errorf("findings not sorted by severity: %+v", findings)
+	}
+}
+
+func TestHandleSubjectAccess(t *testing.T) {
+	client := testClient()
+	client.runWithNamespace = "Resources   Non-Resource URLs   Resource Names   Verbs\npods        []                  []               [*]\n"
+	s := NewSubAgent(client, false)
+
+	resp, err := s.HandleQuery(context.Background(), "what can serviceaccount ci do in build", QueryOptions{})
+	if err != nil {
+		t.Fatalf("HandleQuery: %v", err)
+	}
+	if resp.Type != ResponseTypeResult {
+		t.Fatalf("response type = %s: %s", resp.Type, resp.Message)
+	}
+	if strings.Join(client.lastArgs, " ") != "auth can-i --list --as system:serviceaccount:build:ci" {
+		t.Errorf("kubectl args = %v", client.lastArgs)
+	}
+	out := resp.Data.(string)
+	if !strings.Contains(out, "prod/RoleBinding/ci-deployer -> Role/deployer") {
+		t.Errorf("missing granting binding:\n%s", out)
+	}
+}
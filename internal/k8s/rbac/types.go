@@ -0,0 +1,111 @@
+package rbac
+
+import "context"
+
+// ResponseType indicates the type of response
+type ResponseType string
+
+const (
+	ResponseTypeResult ResponseType = "result"
+	ResponseTypeError  ResponseType = "error"
+)
+
+// QueryType is the kind of RBAC question being asked
+type QueryType string
+
+const (
+	QuerySubjectAccess QueryType = "subject_access" // what can X do
+	QueryWhoCan        QueryType = "who_can"        // who can <verb> <resource>
+	QueryAudit         QueryType = "audit"          // over-broad bindings report
+	QueryList          QueryType = "list"           // list roles and bindings
+)
+
+// Severity ranks audit findings
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+)
+
+// K8sClient defines the interface for kubectl operations needed by rbac
+type K8sClient interface {
+	Run(ctx context.Context, args ...string) (string, error)
+	RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error)
+	RunJSON(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// QueryOptions contains options for RBAC queries
+type QueryOptions struct {
+	Namespace string
+}
+
+// Response from the rbac sub-agent
+type Response struct {
+	Type    ResponseType
+	Data    interface{}
+	Message string
+	Error   error
+}
+
+// PolicyRule mirrors rbac.authorization.k8s.io/v1 PolicyRule
+type PolicyRule struct {
+	Verbs           []string `json:"verbs"`
+	APIGroups       []string `json:"apiGroups,omitempty"`
+	Resources       []string `json:"resources,omitempty"`
+	ResourceNames   []string `json:"resourceNames,omitempty"`
+	NonResourceURLs []string `json:"nonResourceURLs,omitempty"`
+}
+
+// Role is a Role or ClusterRole
+type Role struct {
+	Kind      string       `json:"kind"`
+	Name      string       `json:"name"`
+	Namespace string       `json:"namespace,omitempty"`
+	Rules     []PolicyRule `json:"rules"`
+}
+
+// Subject is a user, group, or service account referenced by a binding
+type Subject struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// RoleRef points a binding at a Role or ClusterRole
+type RoleRef struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// Binding is a RoleBinding or ClusterRoleBinding
+type Binding struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Namespace string    `json:"namespace,omitempty"`
+	RoleRef   RoleRef   `json:"roleRef"`
+	Subjects  []Subject `json:"subjects"`
+}
+
+// Inventory holds every role and binding in the cluster
+type Inventory struct {
+	Roles    []Role    `json:"roles"`
+	Bindings []Binding `json:"bindings"`
+}
+
+// AccessGrant explains how a subject gets a permission
+type AccessGrant struct {
+	Subject   Subject `json:"subject"`
+	Binding   string  `json:"binding"` // Kind/name of the binding
+	Role      string  `json:"role"`    // Kind/name of the role
+	Namespace string  `json:"namespace,omitempty"`
+}
+
+// Finding is an over-broad or risky binding
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Binding  string   `json:"binding"`
+	Subject  string   `json:"subject,omitempty"`
+	Reason   string   `json:"reason"`
+}
@@ -0,0 +1,149 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// dockerHubAuthKey is the key docker login stores Docker Hub credentials under
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// PullAuth is how the cluster gets credentials to pull a private image
+type PullAuth struct {
+	SecretName   string   // imagePullSecret the pods reference; empty when the node identity pulls
+	DockerConfig string   // docker config the plan creates the secret from; empty when it must already exist
+	Notes        []string // what the operator has to set up
+}
+
+// PlanPullAuth decides how pods pull a private image. Cloud registries are
+// read with the node identity of clusters on the same provider; other
+// registries need an imagePullSecret, named in kubernetes.registry.pull_secrets
+// when one already exists in the cluster, or created from the operator's
+// docker login otherwise.
+func PlanPullAuth(ref Ref) PullAuth {
+	if name := configuredSecret(ref); name != "" {
+		return PullAuth{
+			SecretName: name,
+			Notes:      []string{fmt.Sprintf("Pods pull %s with the existing imagePullSecret %s", ref.Host, name)},
+		}
+	}
+
+	switch ref.Kind {
+	case KindECR:
+		return PullAuth{Notes: []string{
+			fmt.Sprintf("Private ECR image: EKS nodes pull it with the node IAM role, which needs AmazonEC2ContainerRegistryReadOnly on account %s (IRSA and Pod Identity do not apply to image pulls)", ref.Account),
+			"Clusters outside EKS need an imagePullSecret from `aws ecr get-login-password`, which expires after 12 hours; name it in kubernetes.registry.pull_secrets",
+		}}
+	case KindGCR, KindArtifactRegistry:
+		return PullAuth{Notes: []string{
+			fmt.Sprintf("Private %s image: GKE nodes pull it with the node service account, which needs roles/artifactregistry.reader on the repository (Workload Identity does not apply to image pulls)", ref.Host),
+			"Clusters outside GKE need an imagePullSecret; name it in kubernetes.registry.pull_secrets",
+		}}
+	case KindACR:
+		return PullAuth{Notes: []string{
+			fmt.Sprintf("Private ACR image: grant the AKS kubelet identity AcrPull with `az aks update --attach-acr %s -n <cluster> -g <resource-group>`", strings.TrimSuffix(ref.Host, ".azurecr.io")),
+			"Clusters outside AKS need an imagePullSecret; name it in kubernetes.registry.pull_secrets",
+		}}
+	}
+
+	auth := PullAuth{SecretName: SecretName(ref)}
+	if path, ok := dockerConfigWithAuth(ref); ok {
+		auth.DockerConfig = path
+		auth.Notes = []string{fmt.Sprintf("Private %s image: the plan creates imagePullSecret %s from %s, which copies every registry login in that file into the cluster", ref.Host, auth.SecretName, path)}
+		return auth
+	}
+	server := ref.Host
+	if ref.Kind == KindDockerHub {
+		server = dockerHubAuthKey
+	}
+	auth.Notes = []string{
+		fmt.Sprintf("Private %s image: no docker login for it was found, so create imagePullSecret %s before applying:", ref.Host, auth.SecretName),
+		fmt.Sprintf("  kubectl create secret docker-registry %s --docker-server=%s --docker-username=<user> --docker-password=<token> -n <namespace>", auth.SecretName, server),
+	}
+	return auth
+}
+
+// SecretName is the imagePullSecret clanker creates for a registry
+func SecretName(ref Ref) string {
+	host := ref.Host
+	if ref.Kind == KindDockerHub {
+		host = "docker.io"
+	}
+	host, _, _ = strings.Cut(host, ":")
+	return strings.ReplaceAll(host, ".", "-") + "-pull"
+}
+
+// configuredSecret returns the existing imagePullSecret configured for the
+// image's registry under kubernetes.registry.pull_secrets:
+//
+//	kubernetes:
+//	  registry:
+//	    pull_secrets:
+//	      - registry: ghcr.io
+//	        secret: ghcr-creds
+func configuredSecret(ref Ref) string {
+	var secrets []struct {
+		Registry string `mapstructure:"registry"`
+		Secret   string `mapstructure:"secret"`
+	}
+	if err := viper.UnmarshalKey("kubernetes.registry.pull_secrets", &secrets); err != nil {
+		return ""
+	}
+	for _, s := range secrets {
+		registry := strings.ToLower(s.Registry)
+		if registry == ref.Host || (ref.Kind == KindDockerHub && registry == "docker.io") {
+			return s.Secret
+		}
+	}
+	return ""
+}
+
+// dockerConfigPath is where docker login keeps credentials
+func dockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// dockerConfigWithAuth returns the docker config path when it holds an
+// inline login for the image's registry. Logins kept by a credential helper
+// are not in the file, so a secret made from it could not pull.
+func dockerConfigWithAuth(ref Ref) (string, bool) {
+	path := dockerConfigPath()
+	if path == "" {
+		return "", false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", false
+	}
+	want := ref.Host
+	if ref.Kind == KindDockerHub {
+		want = dockerHubAuthKey
+	}
+	for key, entry := range config.Auths {
+		host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://"), "/")
+		if (key == want || host == want) && entry.Auth != "" {
+			return path, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,292 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Status is what a check learned about an image's tag
+type Status string
+
+const (
+	StatusFound      Status = "found"
+	StatusNotFound   Status = "not_found"
+	StatusUnverified Status = "unverified"
+)
+
+// checkTimeout bounds the registry probe and the CLI lookup of one image
+const checkTimeout = 30 * time.Second
+
+// manifestTypes are the manifest media types a registry may answer with
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// notFoundMarkers are how the registry CLIs report a missing repository or tag
+var notFoundMarkers = []string{
+	"imagenotfoundexception",
+	"repositorynotfoundexception",
+	"not_found",
+	"not found",
+	"manifest unknown",
+	"no such manifest",
+}
+
+// Result is what clanker knows about an image before deploying it
+type Result struct {
+	Ref     Ref
+	Private bool   // pulling needs credentials
+	Status  Status // whether the tag exists
+	Detail  string // why the tag could not be verified, or what reported it missing
+}
+
+// Runner executes a registry CLI (aws, gcloud, az or docker) and returns
+// its combined output
+type Runner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// Checker checks images against their registries
+type Checker struct {
+	client     *http.Client
+	run        Runner
+	AWSProfile string // profile for ECR lookups; empty uses the default chain
+}
+
+// NewChecker returns a checker that probes registries over HTTPS and falls
+// back to the provider CLIs for private images
+func NewChecker() *Checker {
+	return &Checker{client: &http.Client{Timeout: checkTimeout}, run: runCLI}
+}
+
+// NewCheckerWith returns a checker using the given HTTP client and CLI runner
+func NewCheckerWith(client *http.Client, run Runner) *Checker {
+	return &Checker{client: client, run: run}
+}
+
+// errNoCLI marks a registry CLI that is not installed
+var errNoCLI = errors.New("not installed")
+
+func runCLI(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Errorf("%s %w", name, errNoCLI)
+	}
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return out.Bytes(), err
+}
+
+// Check works out whether the image is private and whether its tag exists.
+// Registries are first asked anonymously, which settles public images; a
+// private image's tag is then looked up with the provider CLI or docker,
+// using the operator's credentials.
+func (c *Checker) Check(ctx context.Context, image string) (Result, error) {
+	ref, err := Parse(image)
+	if err != nil {
+		return Result{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	result := Result{Ref: ref}
+	access, err := c.probe(ctx, ref)
+	switch {
+	case err != nil:
+		// Registry unreachable from here; judge by the registry kind
+		result.Private = ref.CloudRegistry()
+		result.Status, result.Detail = StatusUnverified, err.Error()
+		if !result.Private {
+			return result, nil
+		}
+	case access == accessPublic:
+		result.Status = StatusFound
+		return result, nil
+	case access == accessMissing:
+		result.Status, result.Detail = StatusNotFound, "registry has no such tag"
+		return result, nil
+	default:
+		result.Private = true
+	}
+
+	result.Status, result.Detail = c.lookup(ctx, ref)
+	return result, nil
+}
+
+// access is what an anonymous request for the manifest returned
+type access int
+
+const (
+	accessPublic access = iota
+	accessMissing
+	accessDenied
+)
+
+// probe requests the image manifest anonymously, following the registry's
+// bearer token challenge the way docker pull does
+func (c *Checker) probe(ctx context.Context, ref Ref) (access, error) {
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.Host, ref.Repository, ref.Reference())
+	resp, err := c.head(ctx, manifestURL, "")
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		token, ok, err := c.anonymousToken(ctx, resp.Header.Get("WWW-Authenticate"), ref)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return accessDenied, nil
+		}
+		if resp, err = c.head(ctx, manifestURL, token); err != nil {
+			return 0, err
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return accessPublic, nil
+	case http.StatusNotFound:
+		return accessMissing, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		// Private, or missing: registries do not say which to anonymous callers
+		return accessDenied, nil
+	}
+	return 0, fmt.Errorf("registry %s answered %s", ref.Host, resp.Status)
+}
+
+func (c *Checker) head(ctx context.Context, target, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// anonymousToken fetches a pull token from the realm a bearer challenge
+// names. ok is false when the registry only accepts credentials.
+func (c *Checker) anonymousToken(ctx context.Context, challenge string, ref Ref) (token string, ok bool, err error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", false, nil
+	}
+	fields := parseChallenge(params)
+	if fields["realm"] == "" {
+		return "", false, nil
+	}
+	query := url.Values{}
+	if service := fields["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", ref.Repository))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fields["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", false, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", false, nil
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", false, fmt.Errorf("unreadable token from %s: %w", fields["realm"], err)
+	}
+	if body.Token == "" {
+		body.Token = body.AccessToken
+	}
+	return body.Token, body.Token != "", nil
+}
+
+// parseChallenge reads the key="value" pairs of a WWW-Authenticate header
+func parseChallenge(params string) map[string]string {
+	fields := make(map[string]string)
+	for _, part := range strings.Split(params, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok {
+			fields[strings.ToLower(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return fields
+}
+
+// lookup asks the registry's CLI, with the operator's credentials, whether
+// a private image's tag exists
+func (c *Checker) lookup(ctx context.Context, ref Ref) (Status, string) {
+	var name string
+	var args []string
+	switch ref.Kind {
+	case KindECR:
+		imageID := "imageTag=" + ref.Tag
+		if ref.Digest != "" {
+			imageID = "imageDigest=" + ref.Digest
+		}
+		name = "aws"
+		args = []string{"ecr", "describe-images", "--registry-id", ref.Account, "--region", ref.Region,
+			"--repository-name", ref.Repository, "--image-ids", imageID, "--output", "json"}
+		if c.AWSProfile != "" {
+			args = append(args, "--profile", c.AWSProfile)
+		}
+	case KindGCR, KindArtifactRegistry:
+		name = "gcloud"
+		args = []string{"artifacts", "docker", "images", "describe", ref.String(), "--format", "value(image_summary.digest)"}
+	case KindACR:
+		name = "az"
+		args = []string{"acr", "repository", "show", "--name", strings.TrimSuffix(ref.Host, ".azurecr.io"),
+			"--image", strings.TrimPrefix(ref.String(), ref.Host+"/"), "--output", "json"}
+	default:
+		name = "docker"
+		args = []string{"manifest", "inspect", ref.String()}
+	}
+
+	out, err := c.run(ctx, name, args...)
+	if err == nil {
+		return StatusFound, ""
+	}
+	if errors.Is(err, errNoCLI) {
+		return StatusUnverified, err.Error()
+	}
+	msg := strings.TrimSpace(string(out))
+	lower := strings.ToLower(msg)
+	for _, marker := range notFoundMarkers {
+		if strings.Contains(lower, marker) {
+			return StatusNotFound, firstLine(msg)
+		}
+	}
+	if msg == "" {
+		msg = err.Error()
+	}
+	return StatusUnverified, fmt.Sprintf("%s could not look the image up: %s", name, firstLine(msg))
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
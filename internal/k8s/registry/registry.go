@@ -0,0 +1,131 @@
+// Package registry recognizes the container registry an image is pulled
+// from, works out whether pulling it needs credentials and checks that its
+// tag exists before clanker emits a deploy plan. A plan deploying a private
+// image the cluster cannot pull, or a tag that was never pushed, otherwise
+// only fails once the pods sit in ImagePullBackOff.
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Kind is the registry service hosting an image
+type Kind string
+
+const (
+	KindECR              Kind = "ecr"
+	KindECRPublic        Kind = "ecr-public"
+	KindGCR              Kind = "gcr"
+	KindArtifactRegistry Kind = "artifact-registry"
+	KindACR              Kind = "acr"
+	KindGHCR             Kind = "ghcr"
+	KindDockerHub        Kind = "dockerhub"
+	KindOther            Kind = "other"
+)
+
+// dockerHubHost is where Docker Hub serves the registry API
+const dockerHubHost = "registry-1.docker.io"
+
+var ecrHostPattern = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+// Ref is a parsed image reference
+type Ref struct {
+	Host       string // registry API host, e.g. ghcr.io or 123456789012.dkr.ecr.us-east-1.amazonaws.com
+	Repository string // e.g. library/nginx
+	Tag        string
+	Digest     string
+	Kind       Kind
+	Account    string // ECR registry account
+	Region     string // ECR region
+}
+
+// Reference returns the digest when the image is pinned to one, the tag
+// otherwise
+func (r Ref) Reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// String returns the full image reference
+func (r Ref) String() string {
+	host := r.Host
+	if r.Kind == KindDockerHub {
+		host = "docker.io"
+	}
+	image := host + "/" + r.Repository
+	if r.Digest != "" {
+		return image + "@" + r.Digest
+	}
+	return image + ":" + r.Tag
+}
+
+// CloudRegistry reports whether the image lives in a cloud provider's
+// registry, which clusters on that provider pull from with their node
+// identity rather than an imagePullSecret
+func (r Ref) CloudRegistry() bool {
+	switch r.Kind {
+	case KindECR, KindGCR, KindArtifactRegistry, KindACR:
+		return true
+	}
+	return false
+}
+
+// Parse splits an image reference into registry, repository and tag or
+// digest. Images without a registry host come from Docker Hub, and images
+// without a tag or digest use latest.
+func Parse(image string) (Ref, error) {
+	image = strings.TrimSpace(image)
+	if image == "" || strings.ContainsAny(image, " \t") {
+		return Ref{}, fmt.Errorf("invalid image reference %q", image)
+	}
+
+	var ref Ref
+	name := image
+	if before, digest, ok := strings.Cut(name, "@"); ok {
+		name, ref.Digest = before, digest
+	}
+	if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:colon], name[colon+1:]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	first, rest, hasPath := strings.Cut(name, "/")
+	if hasPath && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Host, ref.Repository = strings.ToLower(first), rest
+	} else {
+		ref.Host, ref.Repository = dockerHubHost, name
+	}
+	if ref.Repository == "" {
+		return Ref{}, fmt.Errorf("invalid image reference %q", image)
+	}
+
+	switch host := ref.Host; {
+	case host == "docker.io" || host == "index.docker.io" || host == dockerHubHost:
+		ref.Host, ref.Kind = dockerHubHost, KindDockerHub
+		if !strings.Contains(ref.Repository, "/") {
+			ref.Repository = "library/" + ref.Repository
+		}
+	case host == "public.ecr.aws":
+		ref.Kind = KindECRPublic
+	case ecrHostPattern.MatchString(host):
+		m := ecrHostPattern.FindStringSubmatch(host)
+		ref.Kind, ref.Account, ref.Region = KindECR, m[1], m[2]
+	case host == "gcr.io" || strings.HasSuffix(host, ".gcr.io"):
+		ref.Kind = KindGCR
+	case strings.HasSuffix(host, "-docker.pkg.dev"):
+		ref.Kind = KindArtifactRegistry
+	case strings.HasSuffix(host, ".azurecr.io"):
+		ref.Kind = KindACR
+	case host == "ghcr.io":
+		ref.Kind = KindGHCR
+	default:
+		ref.Kind = KindOther
+	}
+	return ref, nil
+}
@@ -0,0 +1,196 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		image string
+		want  Ref
+	}{
+		{"nginx", Ref{Host: dockerHubHost, Repository: "library/nginx", Tag: "latest", Kind: KindDockerHub}},
+		{"docker.io/bitnami/redis:7.2", Ref{Host: dockerHubHost, Repository: "bitnami/redis", Tag: "7.2", Kind: KindDockerHub}},
+		{"ghcr.io/acme/api:v1.4.0", Ref{Host: "ghcr.io", Repository: "acme/api", Tag: "v1.4.0", Kind: KindGHCR}},
+		{"123456789012.dkr.ecr.eu-west-1.amazonaws.com/team/web:2024-05", Ref{
+			Host: "123456789012.dkr.ecr.eu-west-1.amazonaws.com", Repository: "team/web", Tag: "2024-05",
+			Kind: KindECR, Account: "123456789012", Region: "eu-west-1",
+		}},
+		{"public.ecr.aws/nginx/nginx:1.25", Ref{Host: "public.ecr.aws", Repository: "nginx/nginx", Tag: "1.25", Kind: KindECRPublic}},
+		{"gcr.io/my-project/worker", Ref{Host: "gcr.io", Repository: "my-project/worker", Tag: "latest", Kind: KindGCR}},
+		{"us-central1-docker.pkg.dev/proj/repo/app:1", Ref{Host: "us-central1-docker.pkg.dev", Repository: "proj/repo/app", Tag: "1", Kind: KindArtifactRegistry}},
+		{"acme.azurecr.io/api@sha256:abc", Ref{Host: "acme.azurecr.io", Repository: "api", Digest: "sha256:abc", Kind: KindACR}},
+		{"localhost:5000/app:dev", Ref{Host: "localhost:5000", Repository: "app", Tag: "dev", Kind: KindOther}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.image)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.image, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.image, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "ghcr.io/", "two words"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRefString(t *testing.T) {
+	for image, want := range map[string]string{
+		"nginx":                     "docker.io/library/nginx:latest",
+		"ghcr.io/acme/api@sha256:1": "ghcr.io/acme/api@sha256:1",
+	} {
+		ref, _ := Parse(image)
+		if got := ref.String(); got != want {
+			t.Errorf("String(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+// fakeRegistry serves manifests the way a registry with token auth does:
+// anonymous requests get a bearer challenge, and the token endpoint only
+// issues tokens for public repositories
+func fakeRegistry(t *testing.T, public map[string]bool, tags map[string]bool) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			repo := strings.TrimSuffix(strings.TrimPrefix(r.URL.Query().Get("scope"), "repository:"), ":pull")
+			if !public[repo] {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"token":"anon-%s"}`, repo)
+			return
+		}
+		repo, ref, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer anon-"+repo {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, srv.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !tags[repo+":"+ref] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCheckProbesAnonymously(t *testing.T) {
+	srv := fakeRegistry(t, map[string]bool{"acme/web": true}, map[string]bool{"acme/web:1": true})
+	host := strings.TrimPrefix(srv.URL, "https://")
+	var looked []string
+	checker := NewCheckerWith(srv.Client(), func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		looked = append(looked, name+" "+strings.Join(args, " "))
+		if strings.HasSuffix(args[len(args)-1], ":gone") {
+			return []byte("manifest unknown"), errors.New("exit status 1")
+		}
+		return nil, nil
+	})
+
+	tests := []struct {
+		image   string
+		private bool
+		status  Status
+	}{
+		{host + "/acme/web:1", false, StatusFound},
+		{host + "/acme/web:2", false, StatusNotFound},
+		{host + "/acme/secret:1", true, StatusFound},
+		{host + "/acme/secret:gone", true, StatusNotFound},
+	}
+	for _, tt := range tests {
+		result, err := checker.Check(context.Background(), tt.image)
+		if err != nil {
+			t.Fatalf("Check(%q): %v", tt.image, err)
+		}
+		if result.Private != tt.private || result.Status != tt.status {
+			t.Errorf("Check(%q) = private %v, %s (%s); want private %v, %s", tt.image, result.Private, result.Status, result.Detail, tt.private, tt.status)
+		}
+	}
+	if len(looked) != 2 || !strings.HasPrefix(looked[0], "docker manifest inspect ") {
+		t.Errorf("CLI lookups = %v, want docker manifest inspect for the two private images", looked)
+	}
+}
+
+func TestLookupUsesProviderCLI(t *testing.T) {
+	var got string
+	checker := NewCheckerWith(nil, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		got = name + " " + strings.Join(args, " ")
+		return []byte("An error occurred (ImageNotFoundException)"), errors.New("exit status 254")
+	})
+	checker.AWSProfile = "prod"
+	ref, _ := Parse("123456789012.dkr.ecr.us-east-1.amazonaws.com/web:v2")
+	status, detail := checker.lookup(context.Background(), ref)
+	if status != StatusNotFound || !strings.Contains(detail, "ImageNotFoundException") {
+		t.Fatalf("lookup = %s, %q", status, detail)
+	}
+	want := "aws ecr describe-images --registry-id 123456789012 --region us-east-1 --repository-name web --image-ids imageTag=v2 --output json --profile prod"
+	if got != want {
+		t.Fatalf("ran %q, want %q", got, want)
+	}
+
+	checker = NewCheckerWith(nil, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, fmt.Errorf("%s %w", name, errNoCLI)
+	})
+	ref, _ = Parse("us-docker.pkg.dev/proj/repo/app:1")
+	if status, detail := checker.lookup(context.Background(), ref); status != StatusUnverified || detail != "gcloud not installed" {
+		t.Fatalf("lookup without gcloud = %s, %q", status, detail)
+	}
+}
+
+func TestPlanPullAuth(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	config := `{"auths":{"ghcr.io":{"auth":"dXNlcjp0b2tlbg=="},"https://index.docker.io/v1/":{}},"credsStore":"desktop"}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ghcr, _ := Parse("ghcr.io/acme/api:1")
+	auth := PlanPullAuth(ghcr)
+	if auth.SecretName != "ghcr-io-pull" || auth.DockerConfig != filepath.Join(dir, "config.json") {
+		t.Fatalf("ghcr auth = %+v", auth)
+	}
+
+	// Docker Hub login kept by the credential helper is not in the file
+	hub, _ := Parse("acme/private:1")
+	auth = PlanPullAuth(hub)
+	if auth.SecretName != "docker-io-pull" || auth.DockerConfig != "" || !strings.Contains(strings.Join(auth.Notes, "\n"), "kubectl create secret docker-registry docker-io-pull") {
+		t.Fatalf("docker hub auth = %+v", auth)
+	}
+
+	ecr, _ := Parse("123456789012.dkr.ecr.us-east-1.amazonaws.com/web:1")
+	auth = PlanPullAuth(ecr)
+	if auth.SecretName != "" || !strings.Contains(auth.Notes[0], "AmazonEC2ContainerRegistryReadOnly") {
+		t.Fatalf("ecr auth = %+v", auth)
+	}
+
+	viper.Set("kubernetes.registry.pull_secrets", []map[string]string{{"registry": "ghcr.io", "secret": "ghcr-creds"}})
+	defer viper.Set("kubernetes.registry.pull_secrets", nil)
+	auth = PlanPullAuth(ghcr)
+	if auth.SecretName != "ghcr-creds" || auth.DockerConfig != "" {
+		t.Fatalf("configured auth = %+v", auth)
+	}
+}
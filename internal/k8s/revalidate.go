@@ -0,0 +1,207 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s/policy"
+)
+
+// ObservedObject is the state of an object a plan changes, recorded when the
+// plan is generated so apply can tell whether it moved underneath the plan
+type ObservedObject struct {
+	Resource   string `json:"resource"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	Generation int64  `json:"generation,omitempty"`
+	Replicas   *int64 `json:"replicas,omitempty"`
+}
+
+// PlanDrift is an object a plan changes that differs from what the plan saw
+type PlanDrift struct {
+	Resource  string `json:"resource"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Problem   string `json:"problem"`
+}
+
+func (d PlanDrift) String() string {
+	s := d.Resource + "/" + d.Name
+	if d.Namespace != "" {
+		s += " in " + d.Namespace
+	}
+	return s + ": " + d.Problem
+}
+
+// ObjectFetcher returns an object as JSON, or nil when it does not exist
+type ObjectFetcher func(ctx context.Context, resource, name, namespace string) ([]byte, error)
+
+// LiveObjectFetcher fetches objects with kubectl get -o json through c
+func LiveObjectFetcher(c *Client) ObjectFetcher {
+	return func(ctx context.Context, resource, name, namespace string) ([]byte, error) {
+		data, err := c.GetJSON(ctx, resource, name, namespace)
+		if err != nil {
+			if strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "not found") {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return data, nil
+	}
+}
+
+// targetsExisting reports whether a verb changes an object that must already
+// exist, as opposed to creating one
+func targetsExisting(verb string) bool {
+	if strings.HasPrefix(verb, "rollout ") || strings.HasPrefix(verb, "set ") {
+		return true
+	}
+	switch verb {
+	case "delete", "scale", "patch", "label", "annotate", "edit", "autoscale",
+		"expose", "cordon", "uncordon", "drain", "taint":
+		return true
+	}
+	return false
+}
+
+// planTargets lists the existing objects a plan's kubectl commands change
+func planTargets(plan *K8sPlan, defaultNamespace string) []policy.Operation {
+	var targets []policy.Operation
+	seen := make(map[string]bool)
+	for _, op := range PlanOperations(plan, defaultNamespace) {
+		if !targetsExisting(op.Verb) || op.Name == "" || op.Resource == "" ||
+			op.Resource == "helmreleases" || op.Namespace == policy.AllNamespaces {
+			continue
+		}
+		key := op.Resource + "/" + op.Name + "/" + op.Namespace
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		targets = append(targets, op)
+	}
+	return targets
+}
+
+// ObservePlan records the current state of every existing object the plan
+// changes. Objects that cannot be fetched are skipped.
+func ObservePlan(ctx context.Context, plan *K8sPlan, defaultNamespace string, fetch ObjectFetcher) {
+	plan.Observed = nil
+	for _, op := range planTargets(plan, defaultNamespace) {
+		data, err := fetch(ctx, op.Resource, op.Name, op.Namespace)
+		if err != nil || data == nil {
+			continue
+		}
+		obs := observeObject(data)
+		obs.Resource, obs.Name, obs.Namespace = op.Resource, op.Name, op.Namespace
+		plan.Observed = append(plan.Observed, obs)
+	}
+}
+
+// RevalidatePlan re-fetches every existing object the plan changes and
+// reports the ones that were deleted, rescaled or modified since the plan
+// was generated. Objects that could not be fetched are returned as warnings.
+func RevalidatePlan(ctx context.Context, plan *K8sPlan, defaultNamespace string, fetch ObjectFetcher) ([]PlanDrift, []string) {
+	var drifts []PlanDrift
+	var warnings []string
+	for _, op := range planTargets(plan, defaultNamespace) {
+		before, recorded := findObserved(plan.Observed, op)
+		namespace := op.Namespace
+		if namespace == "" && recorded {
+			namespace = before.Namespace
+		}
+		drift := PlanDrift{Resource: op.Resource, Name: op.Name, Namespace: namespace}
+
+		data, err := fetch(ctx, op.Resource, op.Name, namespace)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("could not re-check %s: %v", drift.Resource+"/"+drift.Name, err))
+			continue
+		}
+		if data == nil {
+			drift.Problem = "no longer exists"
+			drifts = append(drifts, drift)
+			continue
+		}
+		if !recorded {
+			continue
+		}
+		now := observeObject(data)
+		switch {
+		case before.Replicas != nil && now.Replicas != nil && *before.Replicas != *now.Replicas:
+			drift.Problem = fmt.Sprintf("replica count changed from %d to %d", *before.Replicas, *now.Replicas)
+		case before.Generation != 0 && now.Generation != before.Generation:
+			drift.Problem = fmt.Sprintf("spec changed (generation %d, was %d)", now.Generation, before.Generation)
+		default:
+			continue
+		}
+		drifts = append(drifts, drift)
+	}
+	return drifts, warnings
+}
+
+// findObserved matches an operation to its recorded state. Operations
+// without a namespace match whatever namespace the plan saw.
+func findObserved(observed []ObservedObject, op policy.Operation) (ObservedObject, bool) {
+	for _, obs := range observed {
+		if obs.Resource == op.Resource && obs.Name == op.Name &&
+			(op.Namespace == "" || obs.Namespace == op.Namespace) {
+			return obs, true
+		}
+	}
+	return ObservedObject{}, false
+}
+
+func observeObject(data []byte) ObservedObject {
+	var obj struct {
+		Metadata struct {
+			Generation int64 `json:"generation"`
+		} `json:"metadata"`
+		Spec struct {
+			Replicas *int64 `json:"replicas"`
+		} `json:"spec"`
+	}
+	_ = json.Unmarshal(data, &obj)
+	return ObservedObject{Generation: obj.Metadata.Generation, Replicas: obj.Spec.Replicas}
+}
+
+// observePlan records the state of the objects a freshly generated plan
+// changes. Plans are still returned when the cluster cannot be reached.
+func (a *Agent) observePlan(ctx context.Context, plan *K8sPlan, namespace string) {
+	if a.client == nil {
+		return
+	}
+	ObservePlan(ctx, plan, namespace, LiveObjectFetcher(a.client))
+}
+
+// revalidatePlan refuses a plan whose targets drifted since it was generated
+// unless opts ignore drift
+func (a *Agent) revalidatePlan(ctx context.Context, plan *K8sPlan, opts ApplyOptions) error {
+	if a.client == nil {
+		return nil
+	}
+	drifts, warnings := RevalidatePlan(ctx, plan, a.client.namespace, LiveObjectFetcher(a.client))
+	for _, w := range warnings {
+		fmt.Printf("[k8s-agent] warning: %s\n", w)
+	}
+	if len(drifts) == 0 {
+		return nil
+	}
+	if opts.IgnoreDrift {
+		for _, d := range drifts {
+			fmt.Printf("[k8s-agent] warning: applying despite drift: %s\n", d)
+		}
+		return nil
+	}
+	return DriftError(drifts)
+}
+
+// DriftError describes drifted plan targets as an error
+func DriftError(drifts []PlanDrift) error {
+	lines := make([]string, 0, len(drifts))
+	for _, d := range drifts {
+		lines = append(lines, "  - "+d.String())
+	}
+	return fmt.Errorf("cluster changed since the plan was generated:\n%s\nregenerate the plan, or apply anyway with --ignore-drift", strings.Join(lines, "\n"))
+}
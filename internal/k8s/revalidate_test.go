@@ -0,0 +1,85 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRevalidatePlan(t *testing.T) {
+	plan := &K8sPlan{
+		KubectlCmds: []KubectlCmd{
+			{Args: []string{"kubectl", "scale", "deployment/web", "--replicas", "5"}, Namespace: "shop"},
+			{Args: []string{"set", "image", "deployment/api", "api=api:v2"}, Namespace: "shop"},
+			{Args: []string{"delete", "pvc", "cache"}, Namespace: "shop"},
+			{Args: []string{"label", "node", "worker-1", "tier=gpu"}},
+			{Args: []string{"create", "configmap", "settings"}, Namespace: "shop"},
+		},
+	}
+	live := map[string]string{
+		"deployments/web":              `{"metadata":{"generation":4},"spec":{"replicas":3}}`,
+		"deployments/api":              `{"metadata":{"generation":7},"spec":{"replicas":2}}`,
+		"persistentvolumeclaims/cache": `{"metadata":{"generation":1}}`,
+		"nodes/worker-1":               `{"metadata":{}}`,
+	}
+	var fetched []string
+	fetch := func(ctx context.Context, resource, name, namespace string) ([]byte, error) {
+		fetched = append(fetched, resource+"/"+name+"@"+namespace)
+		if resource == "nodes" && live["nodes/worker-1"] == "" {
+			return nil, errors.New("connection refused")
+		}
+		data, ok := live[resource+"/"+name]
+		if !ok {
+			return nil, nil
+		}
+		return []byte(data), nil
+	}
+
+	ObservePlan(context.Background(), plan, "default", fetch)
+	if len(plan.Observed) != 4 || plan.Observed[0].Namespace != "shop" || *plan.Observed[0].Replicas != 3 {
+		t.Fatalf("observed = %+v", plan.Observed)
+	}
+	if strings.Contains(strings.Join(fetched, ","), "configmaps") {
+		t.Errorf("created objects should not be observed: %v", fetched)
+	}
+
+	// Nothing changed
+	if drifts, warnings := RevalidatePlan(context.Background(), plan, "", fetch); len(drifts) != 0 || len(warnings) != 0 {
+		t.Fatalf("drifts = %v, warnings = %v", drifts, warnings)
+	}
+
+	live["deployments/web"] = `{"metadata":{"generation":5},"spec":{"replicas":8}}`
+	live["deployments/api"] = `{"metadata":{"generation":8},"spec":{"replicas":2}}`
+	delete(live, "persistentvolumeclaims/cache")
+	live["nodes/worker-1"] = ""
+
+	drifts, warnings := RevalidatePlan(context.Background(), plan, "", fetch)
+	var got []string
+	for _, d := range drifts {
+		got = append(got, d.String())
+	}
+	want := "deployments/web in shop: replica count changed from 3 to 8|" +
+		"deployments/api in shop: spec changed (generation 8, was 7)|" +
+		"persistentvolumeclaims/cache in shop: no longer exists"
+	if strings.Join(got, "|") != want {
+		t.Errorf("drifts = %v", got)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "nodes/worker-1") {
+		t.Errorf("warnings = %v", warnings)
+	}
+	if err := DriftError(drifts); !strings.Contains(err.Error(), "--ignore-drift") {
+		t.Errorf("DriftError = %v", err)
+	}
+}
+
+func TestRevalidatePlanWithoutObservations(t *testing.T) {
+	plan := &K8sPlan{KubectlCmds: []KubectlCmd{{Args: []string{"rollout", "restart", "deployment/web"}, Namespace: "shop"}}}
+	fetch := func(ctx context.Context, resource, name, namespace string) ([]byte, error) {
+		return nil, nil
+	}
+	drifts, _ := RevalidatePlan(context.Background(), plan, "", fetch)
+	if len(drifts) != 1 || drifts[0].Problem != "no longer exists" {
+		t.Errorf("drifts = %v", drifts)
+	}
+}
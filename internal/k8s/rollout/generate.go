@@ -0,0 +1,461 @@
+package rollout
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+	"sigs.k8s.io/yaml"
+)
+
+// TrackLabel marks the pods of a native canary or green Deployment so a
+// Service selector can tell them apart from the stable ones
+const TrackLabel = "clanker.dev/track"
+
+// Options tunes generated rollouts
+type Options struct {
+	Pause time.Duration // how long Argo Rollouts and Flagger hold each step before moving on
+}
+
+// DefaultPause is the hold between traffic steps when Options.Pause is unset
+const DefaultPause = 5 * time.Minute
+
+// Generate builds the plan rolling the request's image out to the target
+// with the given controller
+func Generate(req Request, target Target, controller Controller, opts Options) (*plan.K8sPlan, error) {
+	if opts.Pause <= 0 {
+		opts.Pause = DefaultPause
+	}
+	image := req.NewImage(target)
+	if image == target.Image {
+		return nil, fmt.Errorf("deployment %s already runs %s", target.Name, image)
+	}
+	if req.Strategy == StrategyCanary && len(req.Weights) < 2 {
+		return nil, fmt.Errorf("a canary needs a first traffic share below 100%%")
+	}
+	if req.Strategy == StrategyBlueGreen && target.Service == "" {
+		return nil, fmt.Errorf("blue/green needs a Service in front of deployment %s to switch; none in namespace %s selects its pods", target.Name, target.Namespace)
+	}
+
+	g := &generator{req: req, target: target, image: image, pause: opts.Pause}
+	p := &plan.K8sPlan{
+		Version:   plan.CurrentPlanVersion,
+		CreatedAt: time.Now(),
+		Operation: string(req.Strategy),
+		Notes: []string{
+			fmt.Sprintf("Deployment: %s/%s (%d replicas)", target.Namespace, target.Name, target.Replicas),
+			fmt.Sprintf("Image: %s -> %s", target.Image, image),
+		},
+	}
+
+	var err error
+	switch {
+	case controller == ControllerArgo && req.Strategy == StrategyCanary:
+		err = g.argoCanary(p)
+	case controller == ControllerArgo:
+		err = g.argoBlueGreen(p)
+	case controller == ControllerFlagger:
+		err = g.flagger(p)
+	case req.Strategy == StrategyCanary:
+		err = g.nativeCanary(p)
+	default:
+		err = g.nativeBlueGreen(p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+type generator struct {
+	req    Request
+	target Target
+	image  string
+	pause  time.Duration
+}
+
+// kubectl returns a kubectl step in the target's namespace. The phase leads
+// the reason so it survives into the maker plan, which has no phases.
+func (g *generator) kubectl(id, phase, description string, args ...string) plan.Step {
+	return plan.Step{
+		ID:          id,
+		Phase:       phase,
+		Description: description,
+		Reason:      phase + ": " + description,
+		Command:     "kubectl",
+		Args:        append(args, "-n", g.target.Namespace),
+	}
+}
+
+// wait returns a kubectl step that blocks until the condition holds, with a
+// step timeout that outlasts kubectl's own
+func (g *generator) wait(id, phase, description, condition, resource string, timeout time.Duration) plan.Step {
+	step := g.kubectl(id, phase, description, "wait", "--for="+condition, resource, "--timeout="+timeout.String())
+	step.Timeout = (timeout + time.Minute).String()
+	return step
+}
+
+func (g *generator) rolloutStatus(id, phase, deployment string) plan.Step {
+	step := g.kubectl(id, phase, fmt.Sprintf("Wait for deployment %s to become ready", deployment),
+		"rollout", "status", "deployment/"+deployment, "--timeout=10m")
+	step.Timeout = "11m"
+	return step
+}
+
+func (g *generator) setImage(id, phase string) plan.Step {
+	return g.kubectl(id, phase, fmt.Sprintf("Set %s's %s container to %s", g.target.Name, g.target.Container, g.image),
+		"set", "image", "deployment/"+g.target.Name, g.target.Container+"="+g.image)
+}
+
+func (g *generator) apply(id, phase, description string, objects ...map[string]any) (plan.Step, error) {
+	docs := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return plan.Step{}, fmt.Errorf("failed to render manifest: %w", err)
+		}
+		docs = append(docs, strings.TrimSpace(string(data)))
+	}
+	step := g.kubectl(id, phase, description, "apply", "-f", "-")
+	step.Manifest = strings.Join(docs, "\n---\n") + "\n"
+	return step, nil
+}
+
+// splitReplicas divides the target's replicas so about weight percent of
+// the pods run the canary, keeping at least one of each
+func splitReplicas(total int32, weight int) (canary, stable int32) {
+	canary = int32(math.Round(float64(total) * float64(weight) / 100))
+	if canary < 1 {
+		canary = 1
+	}
+	stable = total - canary
+	if stable < 1 {
+		stable = 1
+	}
+	return canary, stable
+}
+
+// nativeCanary runs the new image in a second Deployment whose pods the
+// Service also selects, so traffic follows the replica split, then moves
+// the stable Deployment to the new image and removes the canary
+func (g *generator) nativeCanary(p *plan.K8sPlan) error {
+	t := g.target
+	canaryName := t.Name + "-canary"
+	p.Summary = fmt.Sprintf("Canary %s to %s (%s)", t.Name, g.image, weightList(g.req.Weights))
+
+	var effective []string
+	for i, weight := range g.req.Weights[:len(g.req.Weights)-1] {
+		phase := fmt.Sprintf("canary %d%%", weight)
+		canary, stable := splitReplicas(t.Replicas, weight)
+		effective = append(effective, fmt.Sprintf("%d%% (%d of %d pods)", canary*100/(canary+stable), canary, canary+stable))
+		if i == 0 {
+			step, err := g.apply("create-canary", phase, fmt.Sprintf("Create deployment %s running %s with %d replicas", canaryName, g.image, canary),
+				g.copyDeployment(canaryName, "canary", canary))
+			if err != nil {
+				return err
+			}
+			p.Steps = append(p.Steps, step)
+		} else {
+			p.Steps = append(p.Steps, g.kubectl(fmt.Sprintf("scale-canary-%d", weight), phase,
+				fmt.Sprintf("Scale %s to %d replicas", canaryName, canary), "scale", "deployment/"+canaryName, fmt.Sprintf("--replicas=%d", canary)))
+		}
+		p.Steps = append(p.Steps,
+			g.rolloutStatus(fmt.Sprintf("wait-canary-%d", weight), phase, canaryName),
+			g.kubectl(fmt.Sprintf("scale-stable-%d", weight), phase, fmt.Sprintf("Scale %s to %d replicas", t.Name, stable),
+				"scale", "deployment/"+t.Name, fmt.Sprintf("--replicas=%d", stable)),
+		)
+	}
+
+	phase := "promote 100%"
+	p.Steps = append(p.Steps,
+		g.setImage("promote", phase),
+		g.kubectl("restore-replicas", phase, fmt.Sprintf("Scale %s back to %d replicas", t.Name, t.Replicas),
+			"scale", "deployment/"+t.Name, fmt.Sprintf("--replicas=%d", t.Replicas)),
+		g.rolloutStatus("wait-promoted", phase, t.Name),
+		g.kubectl("delete-canary", phase, "Remove the canary deployment", "delete", "deployment", canaryName),
+	)
+
+	p.Notes = append(p.Notes,
+		"Traffic follows the replica split: "+strings.Join(effective, ", ")+". Exact percentages need Argo Rollouts or Flagger with a traffic router",
+		"Each step waits for its pods to become ready; the apply stops at the first step that fails",
+	)
+	if t.Service == "" {
+		p.Notes = append(p.Notes, fmt.Sprintf("No Service selects %s's pods, so the canary only receives traffic that reaches pods directly", t.Name))
+	}
+	p.Notes = append(p.Notes,
+		fmt.Sprintf("Watch: kubectl get pods -l %s -n %s -L %s", labelSelector(t.Selector), t.Namespace, TrackLabel),
+		fmt.Sprintf("Roll back before promote: kubectl delete deployment %s -n %s && kubectl scale deployment/%s --replicas=%d -n %s",
+			canaryName, t.Namespace, t.Name, t.Replicas, t.Namespace),
+	)
+	return nil
+}
+
+// nativeBlueGreen starts the new image beside the old one, points the
+// Service at it, then moves the original Deployment to the new image and
+// points the Service back
+func (g *generator) nativeBlueGreen(p *plan.K8sPlan) error {
+	t := g.target
+	greenName := t.Name + "-green"
+	p.Summary = fmt.Sprintf("Blue/green %s to %s", t.Name, g.image)
+
+	green, err := g.apply("create-green", "green", fmt.Sprintf("Create deployment %s running %s with %d replicas", greenName, g.image, t.Replicas),
+		g.copyDeployment(greenName, "green", t.Replicas))
+	if err != nil {
+		return err
+	}
+	selectorPath := "/spec/selector/" + strings.ReplaceAll(TrackLabel, "/", "~1")
+	p.Steps = append(p.Steps,
+		green,
+		g.rolloutStatus("wait-green", "green", greenName),
+		g.kubectl("switch-traffic", "switch", fmt.Sprintf("Point service %s at the green pods", t.Service),
+			"patch", "service", t.Service, "--type", "merge", "-p", fmt.Sprintf(`{"spec":{"selector":{%q:"green"}}}`, TrackLabel)),
+		g.setImage("promote", "promote"),
+		g.rolloutStatus("wait-promoted", "promote", t.Name),
+		g.kubectl("restore-selector", "promote", fmt.Sprintf("Point service %s back at %s", t.Service, t.Name),
+			"patch", "service", t.Service, "--type", "json", "-p", fmt.Sprintf(`[{"op":"remove","path":%q}]`, selectorPath)),
+		g.kubectl("delete-green", "promote", "Remove the green deployment", "delete", "deployment", greenName),
+	)
+	p.Notes = append(p.Notes,
+		fmt.Sprintf("Service %s moves to the green pods in one switch once they are ready, then back to %s after it runs the new image", t.Service, t.Name),
+		fmt.Sprintf("Watch: kubectl get endpoints %s -n %s", t.Service, t.Namespace),
+		fmt.Sprintf(`Roll back before promote: kubectl patch service %s -n %s --type json -p '[{"op":"remove","path":"%s"}]'`, t.Service, t.Namespace, selectorPath),
+	)
+	return nil
+}
+
+// argoCanary hands the Deployment's pods to an Argo Rollout whose steps
+// shift traffic weight by weight, pausing between them
+func (g *generator) argoCanary(p *plan.K8sPlan) error {
+	t := g.target
+	p.Summary = fmt.Sprintf("Canary %s to %s (%s) with Argo Rollouts", t.Name, g.image, weightList(g.req.Weights))
+
+	var steps []any
+	for _, weight := range g.req.Weights[:len(g.req.Weights)-1] {
+		steps = append(steps,
+			map[string]any{"setWeight": weight},
+			map[string]any{"pause": map[string]any{"duration": g.pause.String()}},
+		)
+	}
+	return g.argo(p, map[string]any{"canary": map[string]any{"steps": steps}}, len(g.req.Weights)-1)
+}
+
+// argoBlueGreen hands the Deployment's pods to an Argo Rollout that brings
+// the new version up behind a preview Service and promotes it after a pause
+func (g *generator) argoBlueGreen(p *plan.K8sPlan) error {
+	t := g.target
+	p.Summary = fmt.Sprintf("Blue/green %s to %s with Argo Rollouts", t.Name, g.image)
+	strategy := map[string]any{"blueGreen": map[string]any{
+		"activeService":         t.Service,
+		"previewService":        t.Service + "-preview",
+		"autoPromotionEnabled":  true,
+		"autoPromotionSeconds":  int(g.pause.Seconds()),
+		"scaleDownDelaySeconds": 30,
+	}}
+	return g.argo(p, strategy, 1)
+}
+
+func (g *generator) argo(p *plan.K8sPlan, strategy map[string]any, pauses int) error {
+	t := g.target
+	rollout := map[string]any{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata":   map[string]any{"name": t.Name, "namespace": t.Namespace},
+		"spec": map[string]any{
+			"replicas": t.Replicas,
+			"selector": map[string]any{"matchLabels": t.Selector},
+			"workloadRef": map[string]any{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"name":       t.Name,
+				"scaleDown":  "progressively",
+			},
+			"strategy": strategy,
+		},
+	}
+	objects := []map[string]any{rollout}
+	if _, ok := strategy["blueGreen"]; ok {
+		objects = append([]map[string]any{g.previewService()}, objects...)
+	}
+	adopt, err := g.apply("create-rollout", "adopt", fmt.Sprintf("Create Argo Rollout %s managing deployment %s's pods", t.Name, t.Name), objects...)
+	if err != nil {
+		return err
+	}
+
+	rolloutRef := "rollout/" + t.Name
+	total := time.Duration(pauses)*g.pause + 15*time.Minute
+	p.Steps = append(p.Steps,
+		adopt,
+		g.wait("wait-adopted", "adopt", "Wait for the rollout to take over the running pods", "jsonpath={.status.phase}=Healthy", rolloutRef, 10*time.Minute),
+		g.setImage("start", "progress"),
+		g.wait("wait-first-step", "progress", "Wait for the rollout to reach its first pause", "jsonpath={.status.phase}=Paused", rolloutRef, 10*time.Minute),
+		g.wait("wait-promoted", "progress", "Wait for the rollout to promote the new version", "jsonpath={.status.phase}=Healthy", rolloutRef, total),
+	)
+	p.Notes = append(p.Notes,
+		fmt.Sprintf("Argo Rollouts holds each step for %s before moving on; the Deployment is scaled down as the Rollout takes over its pods", g.pause),
+		fmt.Sprintf("Watch: kubectl argo rollouts get rollout %s -n %s --watch", t.Name, t.Namespace),
+		fmt.Sprintf("Promote now: kubectl argo rollouts promote %s -n %s", t.Name, t.Namespace),
+		fmt.Sprintf("Roll back: kubectl argo rollouts abort %s -n %s", t.Name, t.Namespace),
+	)
+	return nil
+}
+
+// flagger creates a Flagger Canary for the Deployment; Flagger then runs
+// the analysis the image change triggers
+func (g *generator) flagger(p *plan.K8sPlan) error {
+	t := g.target
+	if t.Port == 0 {
+		return fmt.Errorf("flagger needs the port deployment %s serves on, but no Service in namespace %s selects its pods", t.Name, t.Namespace)
+	}
+	analysis := map[string]any{
+		"interval":  g.pause.String(),
+		"threshold": 5,
+	}
+	service := map[string]any{"port": t.Port}
+	if port, err := strconv.Atoi(t.TargetPort); err == nil {
+		service["targetPort"] = port
+	} else if t.TargetPort != "" {
+		service["targetPort"] = t.TargetPort
+	}
+	spec := map[string]any{
+		"targetRef": map[string]any{"apiVersion": "apps/v1", "kind": "Deployment", "name": t.Name},
+		"service":   service,
+		"analysis":  analysis,
+	}
+	if g.req.Strategy == StrategyCanary {
+		p.Summary = fmt.Sprintf("Canary %s to %s (%s) with Flagger", t.Name, g.image, weightList(g.req.Weights))
+		steps := g.req.Weights[:len(g.req.Weights)-1]
+		analysis["stepWeights"] = steps
+		analysis["maxWeight"] = steps[len(steps)-1]
+		p.Notes = append(p.Notes, "Flagger shifts weight through the mesh or ingress it is configured with; with the kubernetes provider it can only run blue/green")
+	} else {
+		p.Summary = fmt.Sprintf("Blue/green %s to %s with Flagger", t.Name, g.image)
+		spec["provider"] = "kubernetes"
+		analysis["iterations"] = 3
+	}
+	canary := map[string]any{
+		"apiVersion": "flagger.app/v1beta1",
+		"kind":       "Canary",
+		"metadata":   map[string]any{"name": t.Name, "namespace": t.Namespace},
+		"spec":       spec,
+	}
+	create, err := g.apply("create-canary", "adopt", fmt.Sprintf("Create Flagger canary %s for deployment %s", t.Name, t.Name), canary)
+	if err != nil {
+		return err
+	}
+
+	canaryRef := "canary/" + t.Name
+	p.Steps = append(p.Steps,
+		create,
+		g.wait("wait-initialized", "adopt", "Wait for Flagger to create the primary deployment", "condition=Promoted", canaryRef, 10*time.Minute),
+		g.setImage("start", "progress"),
+		g.wait("wait-progressing", "progress", "Wait for Flagger to start the analysis", "jsonpath={.status.phase}=Progressing", canaryRef, 10*time.Minute),
+		g.wait("wait-promoted", "progress", "Wait for Flagger to promote the new version", "jsonpath={.status.phase}=Succeeded", canaryRef,
+			time.Duration(len(g.req.Weights)+3)*g.pause+15*time.Minute),
+	)
+	p.Notes = append(p.Notes,
+		fmt.Sprintf("Flagger checks the canary every %s and rolls back after 5 failed checks; it serves traffic from %s-primary and owns service %s", g.pause, t.Name, t.Name),
+		fmt.Sprintf("Watch: kubectl get canary %s -n %s --watch", t.Name, t.Namespace),
+		fmt.Sprintf("Details: kubectl describe canary %s -n %s", t.Name, t.Namespace),
+	)
+	return nil
+}
+
+// copyDeployment returns the live Deployment renamed, running the new image
+// with its pods marked with the track label
+func (g *generator) copyDeployment(name, track string, replicas int32) map[string]any {
+	var d map[string]any
+	data, _ := json.Marshal(g.target.deployment)
+	_ = json.Unmarshal(data, &d)
+	if d == nil {
+		d = map[string]any{}
+	}
+	delete(d, "status")
+
+	meta, _ := d["metadata"].(map[string]any)
+	d["metadata"] = map[string]any{
+		"name":      name,
+		"namespace": g.target.Namespace,
+		"labels":    withTrack(meta["labels"], track),
+	}
+	spec, _ := d["spec"].(map[string]any)
+	if spec == nil {
+		spec = map[string]any{}
+		d["spec"] = spec
+	}
+	spec["replicas"] = replicas
+	selector, _ := spec["selector"].(map[string]any)
+	if selector == nil {
+		selector = map[string]any{}
+		spec["selector"] = selector
+	}
+	selector["matchLabels"] = withTrack(selector["matchLabels"], track)
+	template, _ := spec["template"].(map[string]any)
+	if template == nil {
+		template = map[string]any{}
+		spec["template"] = template
+	}
+	templateMeta, _ := template["metadata"].(map[string]any)
+	if templateMeta == nil {
+		templateMeta = map[string]any{}
+		template["metadata"] = templateMeta
+	}
+	templateMeta["labels"] = withTrack(templateMeta["labels"], track)
+	if podSpec, ok := template["spec"].(map[string]any); ok {
+		containers, _ := podSpec["containers"].([]any)
+		for _, c := range containers {
+			if container, ok := c.(map[string]any); ok && container["name"] == g.target.Container {
+				container["image"] = g.image
+			}
+		}
+	}
+	return d
+}
+
+// previewService is the Service Argo Rollouts sends preview traffic to: the
+// active Service's ports under a -preview name
+func (g *generator) previewService() map[string]any {
+	spec := map[string]any{}
+	if live, ok := g.target.service["spec"].(map[string]any); ok {
+		spec["ports"] = live["ports"]
+		spec["selector"] = live["selector"]
+	}
+	return map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   map[string]any{"name": g.target.Service + "-preview", "namespace": g.target.Namespace},
+		"spec":       spec,
+	}
+}
+
+func withTrack(labels any, track string) map[string]any {
+	out := map[string]any{}
+	if m, ok := labels.(map[string]any); ok {
+		for k, v := range m {
+			out[k] = v
+		}
+	}
+	out[TrackLabel] = track
+	return out
+}
+
+func weightList(weights []int) string {
+	parts := make([]string, len(weights))
+	for i, w := range weights {
+		parts[i] = fmt.Sprintf("%d%%", w)
+	}
+	return strings.Join(parts, " -> ")
+}
+
+func labelSelector(labels map[string]string) string {
+	parts := make([]string, 0, len(labels))
+	for k, v := range labels {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
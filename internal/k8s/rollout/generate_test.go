@@ -0,0 +1,246 @@
+package rollout
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+)
+
+// mockK8sClient implements K8sClient for testing. outputs answers Run by
+// its joined args; anything else fails as kubectl would on a missing object.
+type mockK8sClient struct {
+	deployment string
+	outputs    map[string]string
+}
+
+func (m *mockK8sClient) Run(ctx context.Context, args ...string) (string, error) {
+	if out, ok := m.outputs[strings.Join(args, " ")]; ok {
+		return out, nil
+	}
+	return "", errors.New("not found")
+}
+
+func (m *mockK8sClient) GetJSON(ctx context.Context, resourceType, name, namespace string) ([]byte, error) {
+	if m.deployment == "" {
+		return nil, errors.New("not found")
+	}
+	return []byte(m.deployment), nil
+}
+
+const testDeployment = `{
+  "metadata": {"name": "api", "namespace": "shop", "labels": {"app": "api"}},
+  "spec": {
+    "replicas": 4,
+    "selector": {"matchLabels": {"app": "api"}},
+    "template": {
+      "metadata": {"labels": {"app": "api"}},
+      "spec": {"containers": [
+        {"name": "proxy", "image": "envoyproxy/envoy:v1.29"},
+        {"name": "api", "image": "myorg/api:v1"}
+      ]}
+    }
+  },
+  "status": {"readyReplicas": 4}
+}`
+
+const testServices = `{"items": [
+  {"metadata": {"name": "db"}, "spec": {"selector": {"app": "db"}, "ports": [{"port": 5432}]}},
+  {"metadata": {"name": "api"}, "spec": {"selector": {"app": "api"}, "ports": [{"port": 80, "targetPort": 8080}]}}
+]}`
+
+func testClient() *mockK8sClient {
+	return &mockK8sClient{
+		deployment: testDeployment,
+		outputs:    map[string]string{"get services -n shop -o json": testServices},
+	}
+}
+
+func testTarget(t *testing.T) Target {
+	t.Helper()
+	target, err := Inspect(context.Background(), testClient(), "shop", "api")
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	return target
+}
+
+func TestInspect(t *testing.T) {
+	target := testTarget(t)
+	if target.Replicas != 4 || target.Container != "api" || target.Image != "myorg/api:v1" {
+		t.Errorf("target = %+v", target)
+	}
+	if target.Service != "api" || target.Port != 80 || target.TargetPort != "8080" {
+		t.Errorf("service = %q port %d -> %q", target.Service, target.Port, target.TargetPort)
+	}
+
+	if _, err := Inspect(context.Background(), &mockK8sClient{}, "shop", "api"); err == nil {
+		t.Error("expected an error for a missing deployment")
+	}
+}
+
+func TestDetectController(t *testing.T) {
+	ctx := context.Background()
+	argo := &mockK8sClient{outputs: map[string]string{"get crd rollouts.argoproj.io -o name": "customresourcedefinition/rollouts.argoproj.io"}}
+
+	if c, _ := DetectController(ctx, argo, ""); c != ControllerArgo {
+		t.Errorf("detected %q, want argo-rollouts", c)
+	}
+	if c, _ := DetectController(ctx, argo, ControllerNative); c != ControllerNative {
+		t.Errorf("detected %q, want the requested native", c)
+	}
+	if c, _ := DetectController(ctx, &mockK8sClient{}, ""); c != ControllerNative {
+		t.Errorf("detected %q with no CRDs, want native", c)
+	}
+	if _, err := DetectController(ctx, argo, ControllerFlagger); err == nil {
+		t.Error("expected an error when the requested controller is missing")
+	}
+}
+
+func stepIDs(p *plan.K8sPlan) []string {
+	ids := make([]string, len(p.Steps))
+	for i, s := range p.Steps {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+func findStep(t *testing.T, p *plan.K8sPlan, id string) plan.Step {
+	t.Helper()
+	for _, s := range p.Steps {
+		if s.ID == id {
+			return s
+		}
+	}
+	t.Fatalf("no step %s in %v", id, stepIDs(p))
+	return plan.Step{}
+}
+
+func TestGenerateNativeCanary(t *testing.T) {
+	req, _ := ParseRequest("deploy v2 of service api as a canary at 25%")
+	p, err := Generate(req, testTarget(t), ControllerNative, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	want := "create-canary wait-canary-25 scale-stable-25 scale-canary-50 wait-canary-50 scale-stable-50 promote restore-replicas wait-promoted delete-canary"
+	if got := strings.Join(stepIDs(p), " "); got != want {
+		t.Errorf("steps = %s", got)
+	}
+
+	create := findStep(t, p, "create-canary")
+	for _, s := range []string{"name: api-canary", "replicas: 1", "image: myorg/api:v2", "image: envoyproxy/envoy:v1.29", TrackLabel + ": canary"} {
+		if !strings.Contains(create.Manifest, s) {
+			t.Errorf("canary manifest missing %q:\n%s", s, create.Manifest)
+		}
+	}
+	if strings.Contains(create.Manifest, "readyReplicas") {
+		t.Error("canary manifest carries the live status")
+	}
+	if create.Phase != "canary 25%" {
+		t.Errorf("phase = %q", create.Phase)
+	}
+	if got := strings.Join(findStep(t, p, "scale-stable-50").Args, " "); got != "scale deployment/api --replicas=2 -n shop" {
+		t.Errorf("scale-stable-50 args = %s", got)
+	}
+	if got := strings.Join(findStep(t, p, "promote").Args, " "); got != "set image deployment/api api=myorg/api:v2 -n shop" {
+		t.Errorf("promote args = %s", got)
+	}
+	if !strings.Contains(p.Summary, "25% -> 50% -> 100%") {
+		t.Errorf("summary = %q", p.Summary)
+	}
+}
+
+func TestGenerateNativeBlueGreen(t *testing.T) {
+	req, _ := ParseRequest("blue/green deploy myorg/api:v2 to api")
+	p, err := Generate(req, testTarget(t), ControllerNative, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	switchStep := findStep(t, p, "switch-traffic")
+	if got := strings.Join(switchStep.Args, " "); !strings.Contains(got, `patch service api --type merge -p {"spec":{"selector":{"clanker.dev/track":"green"}}}`) {
+		t.Errorf("switch args = %s", got)
+	}
+	restore := findStep(t, p, "restore-selector")
+	if got := strings.Join(restore.Args, " "); !strings.Contains(got, `/spec/selector/clanker.dev~1track`) {
+		t.Errorf("restore args = %s", got)
+	}
+
+	target := testTarget(t)
+	target.Service = ""
+	if _, err := Generate(req, target, ControllerNative, Options{}); err == nil {
+		t.Error("expected an error without a Service to switch")
+	}
+}
+
+func TestGenerateArgo(t *testing.T) {
+	req, _ := ParseRequest("canary v2 for api at 20% and 60%")
+	p, err := Generate(req, testTarget(t), ControllerArgo, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	create := findStep(t, p, "create-rollout")
+	for _, s := range []string{"kind: Rollout", "scaleDown: progressively", "setWeight: 20", "setWeight: 60", "duration: 5m0s"} {
+		if !strings.Contains(create.Manifest, s) {
+			t.Errorf("rollout manifest missing %q:\n%s", s, create.Manifest)
+		}
+	}
+	if wait := findStep(t, p, "wait-promoted"); wait.Timeout != "26m0s" {
+		t.Errorf("wait-promoted timeout = %q", wait.Timeout)
+	}
+
+	req, _ = ParseRequest("blue-green v2 for api")
+	p, err = Generate(req, testTarget(t), ControllerArgo, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	create = findStep(t, p, "create-rollout")
+	for _, s := range []string{"name: api-preview", "previewService: api-preview", "activeService: api"} {
+		if !strings.Contains(create.Manifest, s) {
+			t.Errorf("blue/green manifest missing %q:\n%s", s, create.Manifest)
+		}
+	}
+}
+
+func TestGenerateFlagger(t *testing.T) {
+	req, _ := ParseRequest("canary v2 for api at 10% with flagger")
+	p, err := Generate(req, testTarget(t), ControllerFlagger, Options{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	create := findStep(t, p, "create-canary")
+	for _, s := range []string{"kind: Canary", "maxWeight: 50", "targetPort: 8080", "- 10"} {
+		if !strings.Contains(create.Manifest, s) {
+			t.Errorf("canary manifest missing %q:\n%s", s, create.Manifest)
+		}
+	}
+}
+
+func TestGenerateRejectsUnchangedImage(t *testing.T) {
+	req, _ := ParseRequest("deploy v1 of api as a canary")
+	if _, err := Generate(req, testTarget(t), ControllerNative, Options{}); err == nil {
+		t.Error("expected an error when the image does not change")
+	}
+}
+
+func TestSplitReplicas(t *testing.T) {
+	tests := []struct {
+		total          int32
+		weight         int
+		canary, stable int32
+	}{
+		{10, 10, 1, 9},
+		{4, 25, 1, 3},
+		{4, 50, 2, 2},
+		{1, 10, 1, 1},
+		{3, 90, 3, 1},
+	}
+	for _, tt := range tests {
+		canary, stable := splitReplicas(tt.total, tt.weight)
+		if canary != tt.canary || stable != tt.stable {
+			t.Errorf("splitReplicas(%d, %d) = %d, %d", tt.total, tt.weight, canary, stable)
+		}
+	}
+}
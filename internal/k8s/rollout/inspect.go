@@ -0,0 +1,154 @@
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// deploymentView is the part of a Deployment the planner reads
+type deploymentView struct {
+	Spec struct {
+		Replicas *int32 `json:"replicas"`
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+		Template struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Spec struct {
+				Containers []struct {
+					Name  string `json:"name"`
+					Image string `json:"image"`
+				} `json:"containers"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+// serviceView is the part of a Service the planner reads
+type serviceView struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Selector map[string]string `json:"selector"`
+		Ports    []struct {
+			Port       int32 `json:"port"`
+			TargetPort any   `json:"targetPort"`
+		} `json:"ports"`
+	} `json:"spec"`
+}
+
+// Inspect reads the Deployment a rollout replaces and the Service in front
+// of it
+func Inspect(ctx context.Context, client K8sClient, namespace, name string) (Target, error) {
+	data, err := client.GetJSON(ctx, "deployment", name, namespace)
+	if err != nil {
+		return Target{}, fmt.Errorf("deployment %s not found in namespace %s: %w", name, namespace, err)
+	}
+	var view deploymentView
+	if err := json.Unmarshal(data, &view); err != nil {
+		return Target{}, fmt.Errorf("failed to parse deployment %s: %w", name, err)
+	}
+	containers := view.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return Target{}, fmt.Errorf("deployment %s has no containers", name)
+	}
+
+	target := Target{
+		Name:      name,
+		Namespace: namespace,
+		Replicas:  1,
+		Selector:  view.Spec.Selector.MatchLabels,
+		Container: containers[0].Name,
+		Image:     containers[0].Image,
+	}
+	for _, c := range containers {
+		if c.Name == name {
+			target.Container, target.Image = c.Name, c.Image
+		}
+	}
+	if view.Spec.Replicas != nil {
+		target.Replicas = *view.Spec.Replicas
+	}
+	if err := json.Unmarshal(data, &target.deployment); err != nil {
+		return Target{}, fmt.Errorf("failed to parse deployment %s: %w", name, err)
+	}
+
+	out, err := client.Run(ctx, "get", "services", "-n", namespace, "-o", "json")
+	if err != nil {
+		return target, nil
+	}
+	var services struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &services); err != nil {
+		return target, nil
+	}
+	for _, raw := range services.Items {
+		var svc serviceView
+		if json.Unmarshal(raw, &svc) != nil || !selects(svc.Spec.Selector, view.Spec.Template.Metadata.Labels) {
+			continue
+		}
+		target.Service = svc.Metadata.Name
+		if len(svc.Spec.Ports) > 0 {
+			target.Port = svc.Spec.Ports[0].Port
+			target.TargetPort = portString(svc.Spec.Ports[0].TargetPort, target.Port)
+		}
+		_ = json.Unmarshal(raw, &target.service)
+		break
+	}
+	return target, nil
+}
+
+// selects reports whether a non-empty Service selector matches the labels
+func selects(selector, labels map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// portString renders a Service targetPort, which is a number or a port name
+func portString(targetPort any, port int32) string {
+	switch v := targetPort.(type) {
+	case float64:
+		return strconv.Itoa(int(v))
+	case string:
+		return v
+	}
+	return strconv.Itoa(int(port))
+}
+
+// DetectController returns the controller to roll out with: the one the
+// request asked for, which must be installed, or Argo Rollouts, then
+// Flagger, when their CRDs exist, or plain Deployments
+func DetectController(ctx context.Context, client K8sClient, requested Controller) (Controller, error) {
+	installed := func(c Controller) bool {
+		_, err := client.Run(ctx, "get", "crd", controllerCRDs[c], "-o", "name")
+		return err == nil
+	}
+	switch requested {
+	case ControllerNative:
+		return ControllerNative, nil
+	case ControllerArgo, ControllerFlagger:
+		if !installed(requested) {
+			return "", fmt.Errorf("%s was requested but its CRD %s is not installed in the cluster", requested, controllerCRDs[requested])
+		}
+		return requested, nil
+	}
+	for _, c := range []Controller{ControllerArgo, ControllerFlagger} {
+		if installed(c) {
+			return c, nil
+		}
+	}
+	return ControllerNative, nil
+}
@@ -0,0 +1,121 @@
+package rollout
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s/manifestgen"
+)
+
+var (
+	canaryPattern    = regexp.MustCompile(`\bcanar(?:y|ies)\b`)
+	blueGreenPattern = regexp.MustCompile(`\bblue\s*[/-]?\s*green\b`)
+	weightPattern    = regexp.MustCompile(`\b(\d{1,3})\s*(?:%|percent\b)`)
+	argoPattern      = regexp.MustCompile(`\bargo(?:\s*rollouts?)?\b`)
+	versionPattern   = regexp.MustCompile(`\b(v\d+(?:\.\d+){0,2}(?:-[a-z0-9.]+)?)\b`)
+	targetPatterns   = []*regexp.Regexp{
+		regexp.MustCompile(`\b(?:service|deployment|app|workload)\s+([a-z0-9][a-z0-9-]*)`),
+		regexp.MustCompile(`\b(?:of|for|to)\s+(?:the\s+)?([a-z0-9][a-z0-9-]*)`),
+	}
+)
+
+// notTargets are words the target patterns catch that never name a workload
+var notTargets = map[string]bool{
+	"a": true, "an": true, "the": true, "it": true, "traffic": true, "canary": true,
+	"blue": true, "green": true, "kubernetes": true, "k8s": true, "cluster": true, "production": true,
+}
+
+// defaultFirstWeight is the canary share when the request names none
+const defaultFirstWeight = 10
+
+// ParseRequest reads a canary or blue/green request such as "deploy v2 of
+// service api as a canary at 10%" or "blue/green deploy myorg/api:2.1 for
+// api in the shop namespace". ok is false when the query asks for neither
+// strategy.
+func ParseRequest(query string) (Request, bool) {
+	lower := strings.ToLower(query)
+	var req Request
+	switch {
+	case blueGreenPattern.MatchString(lower):
+		req.Strategy = StrategyBlueGreen
+	case canaryPattern.MatchString(lower):
+		req.Strategy = StrategyCanary
+	default:
+		return Request{}, false
+	}
+
+	// "blue/green" reads like an image reference, so drop it first
+	spec, _ := manifestgen.ParseQuery(blueGreenPattern.ReplaceAllString(lower, " "))
+	req.Namespace = spec.Namespace
+	// A bare word such as "nginx" is more likely the service than a new image
+	if strings.ContainsAny(spec.Image, "/:") {
+		req.Image = spec.Image
+	}
+	if req.Image == "" {
+		if m := versionPattern.FindStringSubmatch(lower); m != nil {
+			req.Version = m[1]
+		}
+	}
+
+	req.Name = parseTarget(lower)
+
+	switch {
+	case argoPattern.MatchString(lower):
+		req.Controller = ControllerArgo
+	case strings.Contains(lower, "flagger"):
+		req.Controller = ControllerFlagger
+	case strings.Contains(lower, "native") || strings.Contains(lower, "without a controller"):
+		req.Controller = ControllerNative
+	}
+
+	if req.Strategy == StrategyCanary {
+		req.Weights = parseWeights(lower)
+	}
+	return req, true
+}
+
+// parseTarget returns the Deployment a request names, skipping words that
+// are part of an image reference
+func parseTarget(lower string) string {
+	for _, pattern := range targetPatterns {
+		for _, m := range pattern.FindAllStringSubmatchIndex(lower, -1) {
+			name, rest := lower[m[2]:m[3]], lower[m[3]:]
+			if strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, ":") || (len(rest) > 1 && rest[0] == '.' && rest[1] != ' ') {
+				continue
+			}
+			if _, err := strconv.Atoi(name); err == nil || notTargets[name] || versionPattern.MatchString(name) {
+				continue
+			}
+			return name
+		}
+	}
+	return ""
+}
+
+// parseWeights returns the traffic steps a canary request names, adding a
+// halfway step after a small first share and always ending at 100
+func parseWeights(lower string) []int {
+	seen := make(map[int]bool)
+	var weights []int
+	for _, m := range weightPattern.FindAllStringSubmatch(lower, -1) {
+		w, err := strconv.Atoi(m[1])
+		if err != nil || w < 1 || w > 100 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		weights = append(weights, w)
+	}
+	sort.Ints(weights)
+	if len(weights) == 0 {
+		weights = []int{defaultFirstWeight}
+	}
+	if len(weights) == 1 && weights[0] < 50 {
+		weights = append(weights, 50)
+	}
+	if weights[len(weights)-1] != 100 {
+		weights = append(weights, 100)
+	}
+	return weights
+}
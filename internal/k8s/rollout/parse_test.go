@@ -0,0 +1,83 @@
+package rollout
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Request
+	}{
+		{
+			"deploy v2 of service checkout as a canary at 10%",
+			Request{Strategy: StrategyCanary, Name: "checkout", Version: "v2", Weights: []int{10, 50, 100}},
+		},
+		{
+			"canary myorg/api:2.1 for api in the shop namespace with 5%, 25% and 50% steps using argo",
+			Request{Strategy: StrategyCanary, Name: "api", Namespace: "shop", Image: "myorg/api:2.1", Weights: []int{5, 25, 50, 100}, Controller: ControllerArgo},
+		},
+		{
+			"blue/green deploy v1.4.0 of deployment web with flagger",
+			Request{Strategy: StrategyBlueGreen, Name: "web", Version: "v1.4.0", Controller: ControllerFlagger},
+		},
+		{
+			"roll out v3 to payments as a canary",
+			Request{Strategy: StrategyCanary, Name: "payments", Version: "v3", Weights: []int{10, 50, 100}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, ok := ParseRequest(tt.query)
+			if !ok {
+				t.Fatal("expected a rollout request")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRequestIgnoresOtherQueries(t *testing.T) {
+	for _, q := range []string{"deploy nginx with 3 replicas", "show rollout status of api", "scale web to 5"} {
+		if _, ok := ParseRequest(q); ok {
+			t.Errorf("%q parsed as a rollout request", q)
+		}
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"canary", []int{10, 50, 100}},
+		{"canary at 60%", []int{60, 100}},
+		{"canary 20 percent then 100%", []int{20, 100}},
+		{"canary 30% 10% 30%", []int{10, 30, 100}},
+	}
+	for _, tt := range tests {
+		if got := parseWeights(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseWeights(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestNewImage(t *testing.T) {
+	tests := []struct {
+		current, version, want string
+	}{
+		{"myorg/api:v1", "v2", "myorg/api:v2"},
+		{"registry.local:5000/api", "v2", "registry.local:5000/api:v2"},
+		{"ghcr.io/acme/api:v1@sha256:abc", "v2", "ghcr.io/acme/api:v2"},
+	}
+	for _, tt := range tests {
+		got := Request{Version: tt.version}.NewImage(Target{Image: tt.current})
+		if got != tt.want {
+			t.Errorf("NewImage(%q, %q) = %q, want %q", tt.current, tt.version, got, tt.want)
+		}
+	}
+}
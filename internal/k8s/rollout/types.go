@@ -0,0 +1,80 @@
+// Package rollout plans progressive delivery of a new image to an existing
+// Deployment: a canary that takes a growing share of traffic, or a
+// blue/green switch. Plans use Argo Rollouts or Flagger when the cluster
+// runs them, and plain Deployments and Service selectors otherwise.
+package rollout
+
+import (
+	"context"
+	"strings"
+)
+
+// K8sClient defines the kubectl operations the planner needs
+// This interface is satisfied by k8s.Client
+type K8sClient interface {
+	Run(ctx context.Context, args ...string) (string, error)
+	GetJSON(ctx context.Context, resourceType, name, namespace string) ([]byte, error)
+}
+
+// Strategy is how traffic moves to the new version
+type Strategy string
+
+const (
+	StrategyCanary    Strategy = "canary"
+	StrategyBlueGreen Strategy = "blue-green"
+)
+
+// Controller is what carries the rollout out
+type Controller string
+
+const (
+	ControllerNative  Controller = "native"
+	ControllerArgo    Controller = "argo-rollouts"
+	ControllerFlagger Controller = "flagger"
+)
+
+// controllerCRDs are the CRDs that show a controller is installed
+var controllerCRDs = map[Controller]string{
+	ControllerArgo:    "rollouts.argoproj.io",
+	ControllerFlagger: "canaries.flagger.app",
+}
+
+// Request is a parsed rollout request
+type Request struct {
+	Strategy   Strategy
+	Name       string // Deployment to roll out
+	Namespace  string
+	Image      string     // new image; empty when only Version is given
+	Version    string     // new tag, e.g. v2, applied to the current image
+	Weights    []int      // canary traffic percentages in order, ending at 100
+	Controller Controller // asked for in the request; empty detects one
+}
+
+// Target is the live Deployment a rollout replaces
+type Target struct {
+	Name       string
+	Namespace  string
+	Replicas   int32
+	Container  string            // container whose image changes
+	Image      string            // its current image
+	Selector   map[string]string // the Deployment's matchLabels
+	Service    string            // Service selecting the pods; empty when none does
+	Port       int32             // the Service's first port
+	TargetPort string            // and the container port it forwards to
+
+	deployment map[string]any
+	service    map[string]any
+}
+
+// NewImage returns the image the request rolls out: its image, or the
+// target's image with the requested tag
+func (r Request) NewImage(t Target) string {
+	if r.Image != "" {
+		return r.Image
+	}
+	base, _, _ := strings.Cut(t.Image, "@")
+	if colon := strings.LastIndex(base, ":"); colon > strings.LastIndex(base, "/") {
+		base = base[:colon]
+	}
+	return base + ":" + r.Version
+}
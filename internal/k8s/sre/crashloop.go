@@ -0,0 +1,470 @@
+package sre
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Root cause categories produced by the crash-loop analyzer
+const (
+	RootCauseOOMKilled        = "oom_killed"
+	RootCauseKilled           = "killed"            // SIGKILL without OOM: liveness probe, eviction, preStop timeout
+	RootCauseTerminated       = "terminated"        // SIGTERM, usually a failed liveness probe
+	RootCauseStartupCommand   = "startup_command"   // exit 126/127, entrypoint missing or not executable
+	RootCauseConfiguration    = "configuration"     // missing env, file, or secret
+	RootCauseDependency       = "dependency"        // upstream refused/timed out
+	RootCauseApplicationError = "application_error" // panic, exception, non-zero exit
+)
+
+// recentChangeWindow is how far before the first observed crash a rollout
+// or config edit still counts as a likely trigger
+const recentChangeWindow = 24 * time.Hour
+
+// maxRootCausePods caps how many crashing pods one report analyzes; replicas
+// of the same workload almost always share a cause
+const maxRootCausePods = 3
+
+// previousLogTail is how many lines of the crashed container's log are kept
+const previousLogTail = 100
+
+// crashLogPatterns classify the previous container's log, checked in order
+var crashLogPatterns = []struct {
+	category string
+	patterns []string
+	summary  string
+}{
+	{RootCauseOOMKilled, []string{"out of memory", "outofmemoryerror", "cannot allocate memory", "javascript heap out of memory"},
+		"the process ran out of memory"},
+	{RootCauseConfiguration, []string{"no such file or directory", "permission denied", "environment variable", "env var", "missing required",
+		"is not set", "invalid configuration", "config error", "could not load config", "secret", "certificate"},
+		"the container is missing or misreading configuration"},
+	{RootCauseDependency, []string{"connection refused", "no such host", "i/o timeout", "connection reset", "deadline exceeded",
+		"could not connect", "failed to connect", "unable to connect", "dial tcp"},
+		"a dependency the container needs at startup is unreachable"},
+	{RootCauseApplicationError, []string{"panic:", "traceback (most recent call last)", "exception", "fatal error", "segmentation fault", "unhandled"},
+		"the application crashes on its own error"},
+}
+
+// crashPod is the subset of pod JSON the analyzer needs
+type crashPod struct {
+	Metadata struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		OwnerReferences []struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"ownerReferences"`
+	} `json:"metadata"`
+	Spec struct {
+		Containers []struct {
+			Name      string `json:"name"`
+			Resources struct {
+				Requests map[string]string `json:"requests"`
+				Limits   map[string]string `json:"limits"`
+			} `json:"resources"`
+			Env []struct {
+				ValueFrom *struct {
+					ConfigMapKeyRef *struct {
+						Name string `json:"name"`
+					} `json:"configMapKeyRef"`
+					SecretKeyRef *struct {
+						Name string `json:"name"`
+					} `json:"secretKeyRef"`
+				} `json:"valueFrom"`
+			} `json:"env"`
+			EnvFrom []struct {
+				ConfigMapRef *struct {
+					Name string `json:"name"`
+				} `json:"configMapRef"`
+				SecretRef *struct {
+					Name string `json:"name"`
+				} `json:"secretRef"`
+			} `json:"envFrom"`
+		} `json:"containers"`
+		Volumes []struct {
+			ConfigMap *struct {
+				Name string `json:"name"`
+			} `json:"configMap"`
+			Secret *struct {
+				SecretName string `json:"secretName"`
+			} `json:"secret"`
+		} `json:"volumes"`
+	} `json:"spec"`
+	Status struct {
+		ContainerStatuses []struct {
+			Name         string `json:"name"`
+			RestartCount int    `json:"restartCount"`
+			State        struct {
+				Waiting *struct {
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"waiting"`
+				Terminated *crashTermination `json:"terminated"`
+			} `json:"state"`
+			LastState struct {
+				Terminated *crashTermination `json:"terminated"`
+			} `json:"lastState"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+type crashTermination struct {
+	ExitCode   int    `json:"exitCode"`
+	Signal     int    `json:"signal"`
+	Reason     string `json:"reason"`
+	Message    string `json:"message"`
+	StartedAt  string `json:"startedAt"`
+	FinishedAt string `json:"finishedAt"`
+}
+
+// AnalyzeCrashLoop inspects every crash-looping or OOM-killed container in
+// a pod: it pulls the previous container's logs, the last termination state,
+// resource settings, and rollouts or ConfigMap/Secret edits shortly before
+// the crashes, then classifies the most likely cause.
+func (d *DiagnosticsManager) AnalyzeCrashLoop(ctx context.Context, podName, namespace string) ([]RootCause, error) {
+	data, err := d.client.RunJSON(ctx, "get", "pod", podName, "-n", namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pod: %w", err)
+	}
+	var pod crashPod
+	if err := json.Unmarshal(data, &pod); err != nil {
+		return nil, fmt.Errorf("failed to parse pod: %w", err)
+	}
+	if pod.Metadata.Namespace == "" {
+		pod.Metadata.Namespace = namespace
+	}
+
+	var causes []RootCause
+	var changes []ConfigChange
+	changesLoaded := false
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		term := cs.LastState.Terminated
+		if term == nil {
+			term = cs.State.Terminated
+		}
+		waitingReason := ""
+		if cs.State.Waiting != nil {
+			waitingReason = cs.State.Waiting.Reason
+		}
+		if waitingReason != "CrashLoopBackOff" && (term == nil || term.Reason != "OOMKilled") {
+			continue
+		}
+
+		rc := RootCause{
+			Pod:          podName,
+			Namespace:    namespace,
+			Container:    cs.Name,
+			RestartCount: cs.RestartCount,
+		}
+		if term != nil {
+			rc.LastTermination = &TerminationInfo{
+				Reason:   term.Reason,
+				ExitCode: term.ExitCode,
+				Signal:   term.Signal,
+				Message:  strings.TrimSpace(term.Message),
+			}
+			if t, err := time.Parse(time.RFC3339, term.StartedAt); err == nil {
+				rc.LastTermination.StartedAt = t
+			}
+			if t, err := time.Parse(time.RFC3339, term.FinishedAt); err == nil {
+				rc.LastTermination.FinishedAt = t
+			}
+		}
+		for _, c := range pod.Spec.Containers {
+			if c.Name == cs.Name {
+				rc.Requests = ResourceList{CPU: c.Resources.Requests["cpu"], Memory: c.Resources.Requests["memory"]}
+				rc.Limits = ResourceList{CPU: c.Resources.Limits["cpu"], Memory: c.Resources.Limits["memory"]}
+			}
+		}
+
+		logs, err := d.client.Run(ctx, "logs", podName, "-n", namespace, "-c", cs.Name, "--previous", "--tail", fmt.Sprintf("%d", previousLogTail))
+		if err != nil {
+			rc.Evidence = append(rc.Evidence, fmt.Sprintf("previous logs unavailable: %v", err))
+		} else {
+			rc.PreviousLogs = lastNonEmptyLines(logs, 20)
+		}
+
+		if !changesLoaded {
+			changes = d.recentConfigChanges(ctx, &pod)
+			changesLoaded = true
+		}
+		classifyCrash(&rc, logs, changes)
+		causes = append(causes, rc)
+	}
+
+	return causes, nil
+}
+
+// classifyCrash fills Category, Summary, Confidence, Evidence, and
+// RecentChanges from the termination state, logs, and change history
+func classifyCrash(rc *RootCause, logs string, changes []ConfigChange) {
+	logsLower := strings.ToLower(logs)
+	term := rc.LastTermination
+
+	if term != nil {
+		rc.Evidence = append(rc.Evidence, fmt.Sprintf("last termination: %s (exit code %d)", orUnknown(term.Reason), term.ExitCode))
+	}
+
+	// Changes landing before the last crash, within the window
+	cutoff := time.Now()
+	if term != nil && !term.FinishedAt.IsZero() {
+		cutoff = term.FinishedAt
+	}
+	for _, change := range changes {
+		if !change.ChangedAt.After(cutoff) && cutoff.Sub(change.ChangedAt) <= recentChangeWindow {
+			rc.RecentChanges = append(rc.RecentChanges, change)
+		}
+	}
+
+	switch {
+	case term != nil && term.Reason == "OOMKilled":
+		rc.Category = RootCauseOOMKilled
+		rc.Confidence = "high"
+		limit := rc.Limits.Memory
+		if limit == "" {
+			rc.Summary = "Container was OOM killed with no memory limit set; the node ran out of memory"
+		} else {
+			rc.Summary = fmt.Sprintf("Container was OOM killed at its %s memory limit", limit)
+		}
+	case term != nil && (term.ExitCode == 126 || term.ExitCode == 127):
+		rc.Category = RootCauseStartupCommand
+		rc.Confidence = "high"
+		rc.Summary = fmt.Sprintf("Entrypoint failed with exit code %d; the command is missing or not executable in the image", term.ExitCode)
+	default:
+		for _, p := range crashLogPatterns {
+			if match := firstMatchingLine(logs, logsLower, p.patterns); match != "" {
+				rc.Category = p.category
+				rc.Confidence = "medium"
+				rc.Summary = "Previous container logs suggest " + p.summary
+				rc.Evidence = append(rc.Evidence, "log: "+match)
+				break
+			}
+		}
+	}
+
+	if rc.Category == "" && term != nil {
+		switch term.ExitCode {
+		case 137:
+			rc.Category = RootCauseKilled
+			rc.Confidence = "medium"
+			rc.Summary = "Container was SIGKILLed without an OOM; check liveness probes and eviction events"
+		case 143:
+			rc.Category = RootCauseTerminated
+			rc.Confidence = "medium"
+			rc.Summary = "Container was SIGTERMed; a failing liveness probe is the usual cause"
+		}
+	}
+	if rc.Category == "" {
+		rc.Category = RootCauseApplicationError
+		rc.Confidence = "low"
+		rc.Summary = "Container exits non-zero without a recognisable error in its logs"
+	}
+
+	if len(rc.RecentChanges) > 0 {
+		latest := rc.RecentChanges[len(rc.RecentChanges)-1]
+		rc.Evidence = append(rc.Evidence, fmt.Sprintf("%s/%s changed %s before the crash", latest.Kind, latest.Name,
+			cutoff.Sub(latest.ChangedAt).Round(time.Minute)))
+		if rc.Confidence == "low" {
+			rc.Confidence = "medium"
+			rc.Summary += "; it started after a recent change"
+		}
+	}
+}
+
+// recentConfigChanges lists the owning ReplicaSet rollout and the last edit
+// of every ConfigMap and Secret the pod consumes, oldest first
+func (d *DiagnosticsManager) recentConfigChanges(ctx context.Context, pod *crashPod) []ConfigChange {
+	var changes []ConfigChange
+	namespace := pod.Metadata.Namespace
+
+	for _, owner := range pod.Metadata.OwnerReferences {
+		if owner.Kind != "ReplicaSet" {
+			continue
+		}
+		if change, ok := d.objectChange(ctx, "ReplicaSet", owner.Name, namespace); ok {
+			change.Detail = "rollout"
+			changes = append(changes, change)
+		}
+	}
+
+	seen := map[string]bool{}
+	refs := func(kind, name string) {
+		if name == "" || seen[kind+"/"+name] {
+			return
+		}
+		seen[kind+"/"+name] = true
+		if change, ok := d.objectChange(ctx, kind, name, namespace); ok {
+			changes = append(changes, change)
+		}
+	}
+	for _, c := range pod.Spec.Containers {
+		for _, e := range c.EnvFrom {
+			if e.ConfigMapRef != nil {
+				refs("ConfigMap", e.ConfigMapRef.Name)
+			}
+			if e.SecretRef != nil {
+				refs("Secret", e.SecretRef.Name)
+			}
+		}
+		for _, e := range c.Env {
+			if e.ValueFrom == nil {
+				continue
+			}
+			if e.ValueFrom.ConfigMapKeyRef != nil {
+				refs("ConfigMap", e.ValueFrom.ConfigMapKeyRef.Name)
+			}
+			if e.ValueFrom.SecretKeyRef != nil {
+				refs("Secret", e.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+	for _, v := range pod.Spec.Volumes {
+		if v.ConfigMap != nil {
+			refs("ConfigMap", v.ConfigMap.Name)
+		}
+		if v.Secret != nil {
+			refs("Secret", v.Secret.SecretName)
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].ChangedAt.Before(changes[j].ChangedAt) })
+	return changes
+}
+
+// objectChange reports when an object was last written, using the newest
+// managedFields entry and falling back to its creation time. Only metadata
+// is requested so Secret data is never read.
+func (d *DiagnosticsManager) objectChange(ctx context.Context, kind, name, namespace string) (ConfigChange, bool) {
+	output, err := d.client.Run(ctx, "get", strings.ToLower(kind), name, "-n", namespace,
+		"--show-managed-fields", "-o", "jsonpath={.metadata}")
+	if err != nil {
+		logger.Debugf("could not read %s/%s: %v", kind, name, err)
+		return ConfigChange{}, false
+	}
+	var meta struct {
+		CreationTimestamp string            `json:"creationTimestamp"`
+		Annotations       map[string]string `json:"annotations"`
+		ManagedFields     []struct {
+			Time string `json:"time"`
+		} `json:"managedFields"`
+	}
+	if err := json.Unmarshal([]byte(output), &meta); err != nil {
+		return ConfigChange{}, false
+	}
+
+	var changed time.Time
+	if t, err := time.Parse(time.RFC3339, meta.CreationTimestamp); err == nil {
+		changed = t
+	}
+	for _, mf := range meta.ManagedFields {
+		if t, err := time.Parse(time.RFC3339, mf.Time); err == nil && t.After(changed) {
+			changed = t
+		}
+	}
+	if changed.IsZero() {
+		return ConfigChange{}, false
+	}
+
+	return ConfigChange{
+		Kind:      kind,
+		Name:      name,
+		Revision:  meta.Annotations["deployment.kubernetes.io/revision"],
+		ChangedAt: changed,
+	}, true
+}
+
+// attachRootCauses runs the crash-loop analyzer when the report contains
+// crash or OOM issues for pods
+func (d *DiagnosticsManager) attachRootCauses(ctx context.Context, report *DiagnosticReport) {
+	seen := map[string]bool{}
+	for _, issue := range report.Issues {
+		if issue.ResourceType != ResourcePod || seen[issue.ResourceName] {
+			continue
+		}
+		if issue.Category != CategoryCrash && issue.Category != CategoryResourceLimit {
+			continue
+		}
+		if len(seen) >= maxRootCausePods {
+			break
+		}
+		seen[issue.ResourceName] = true
+		namespace := issue.Namespace
+		if namespace == "" {
+			namespace = report.Namespace
+		}
+		causes, err := d.AnalyzeCrashLoop(ctx, issue.ResourceName, namespace)
+		if err != nil {
+			logger.Debugf("crash-loop analysis for %s failed: %v", issue.ResourceName, err)
+			continue
+		}
+		report.RootCauses = append(report.RootCauses, causes...)
+	}
+}
+
+// FormatRootCause renders a root cause as indented report lines
+func FormatRootCause(rc RootCause) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s/%s container %s (%d restarts) [%s, %s confidence]\n",
+		rc.Namespace, rc.Pod, rc.Container, rc.RestartCount, rc.Category, rc.Confidence))
+	sb.WriteString(fmt.Sprintf("  %s\n", rc.Summary))
+	if rc.Limits.Memory != "" || rc.Limits.CPU != "" || rc.Requests.Memory != "" || rc.Requests.CPU != "" {
+		sb.WriteString(fmt.Sprintf("  resources: requests cpu=%s memory=%s, limits cpu=%s memory=%s\n",
+			orUnknown(rc.Requests.CPU), orUnknown(rc.Requests.Memory), orUnknown(rc.Limits.CPU), orUnknown(rc.Limits.Memory)))
+	}
+	for _, e := range rc.Evidence {
+		sb.WriteString(fmt.Sprintf("  - %s\n", e))
+	}
+	for _, c := range rc.RecentChanges {
+		line := fmt.Sprintf("  changed: %s/%s at %s", c.Kind, c.Name, c.ChangedAt.Format(time.RFC3339))
+		if c.Revision != "" {
+			line += " (revision " + c.Revision + ")"
+		}
+		sb.WriteString(line + "\n")
+	}
+	if len(rc.PreviousLogs) > 0 {
+		sb.WriteString("  previous container log tail:\n")
+		for _, l := range rc.PreviousLogs {
+			sb.WriteString(fmt.Sprintf("    %s\n", l))
+		}
+	}
+	return sb.String()
+}
+
+func firstMatchingLine(logs, logsLower string, patterns []string) string {
+	lines := strings.Split(logs, "\n")
+	lowerLines := strings.Split(logsLower, "\n")
+	// Search from the end; the fatal line is usually last
+	for i := len(lowerLines) - 1; i >= 0; i-- {
+		for _, p := range patterns {
+			if strings.Contains(lowerLines[i], p) {
+				return strings.TrimSpace(lines[i])
+			}
+		}
+	}
+	return ""
+}
+
+func lastNonEmptyLines(output string, n int) []string {
+	var lines []string
+	for _, l := range strings.Split(output, "\n") {
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
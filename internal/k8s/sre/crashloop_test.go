@@ -0,0 +1,150 @@
+package sre
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// crashMockClient answers `get <resource> <name>` from objects and
+// `logs --previous` from logs
+type crashMockClient struct {
+	mockK8sClient
+	objects map[string]string
+	logs    string
+}
+
+func (m *crashMockClient) RunJSON(ctx context.Context, args ...string) ([]byte, error) {
+	if obj, ok := m.objects[args[1]+"/"+args[2]]; ok {
+		return []byte(obj), nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (m *crashMockClient) Run(ctx context.Context, args ...string) (string, error) {
+	if args[0] == "get" {
+		out, err := m.RunJSON(ctx, args...)
+		return string(out), err
+	}
+	m.runCalls = append(m.runCalls, args)
+	return m.logs, nil
+}
+
+const crashingPodJSON = `{
+  "metadata": {"name": "api-5c8d7-x1", "namespace": "prod", "ownerReferences": [{"kind": "ReplicaSet", "name": "api-5c8d7"}]},
+  "spec": {
+    "containers": [{
+      "name": "api",
+      "resources": {"requests": {"cpu": "100m", "memory": "128Mi"}, "limits": {"memory": "256Mi"}},
+      "envFrom": [{"configMapRef": {"name": "api-config"}}]
+    }]
+  },
+  "status": {
+    "containerStatuses": [{
+      "name": "api",
+      "restartCount": 7,
+      "state": {"waiting": {"reason": "CrashLoopBackOff"}},
+      "lastState": {"terminated": {"exitCode": %EXIT%, "reason": "%REASON%", "finishedAt": "2026-10-15T10:00:00Z"}}
+    }]
+  }
+}`
+
+func crashPodJSON(exitCode, reason string) string {
+	return strings.NewReplacer("%EXIT%", exitCode, "%REASON%", reason).Replace(crashingPodJSON)
+}
+
+func TestAnalyzeCrashLoopOOM(t *testing.T) {
+	client := &crashMockClient{objects: map[string]string{"pod/api-5c8d7-x1": crashPodJSON("137", "OOMKilled")}}
+	d := NewDiagnosticsManager(client, false)
+
+	causes, err := d.AnalyzeCrashLoop(context.Background(), "api-5c8d7-x1", "prod")
+	if err != nil {
+		t.Fatalf("AnalyzeCrashLoop: %v", err)
+	}
+	if len(causes) != 1 {
+		t.Fatalf("expected 1 root cause, got %d", len(causes))
+	}
+	rc := causes[0]
+	if rc.Category != RootCauseOOMKilled || rc.Confidence != "high" {
+		t.Errorf("category = %s/%s, want oom_killed/high", rc.Category, rc.Confidence)
+	}
+	if rc.Limits.Memory != "256Mi" || !strings.Contains(rc.Summary, "256Mi") {
+		t.Errorf("limits = %+v, summary = %q", rc.Limits, rc.Summary)
+	}
+	if rc.RestartCount != 7 || rc.LastTermination == nil || rc.LastTermination.ExitCode != 137 {
+		t.Errorf("restart/termination not captured: %+v", rc)
+	}
+
+	call := strings.Join(client.runCalls[0], " ")
+	if !strings.Contains(call, "--previous") || !strings.Contains(call, "-c api") {
+		t.Errorf("logs call = %q", call)
+	}
+}
+
+func TestAnalyzeCrashLoopLogsAndRecentChange(t *testing.T) {
+	client := &crashMockClient{
+		objects: map[string]string{
+			"pod/api-5c8d7-x1": crashPodJSON("1", "Error"),
+			"replicaset/api-5c8d7": `{"creationTimestamp": "2026-10-15T09:40:00Z",
+				"annotations": {"deployment.kubernetes.io/revision": "12"}}`,
+			"configmap/api-config": `{"creationTimestamp": "2026-01-01T00:00:00Z",
+				"managedFields": [{"time": "2026-10-15T09:30:00Z"}]}`,
+		},
+		logs: "starting server\nloading config\nerror: dial tcp 10.0.0.5:5432: connect: connection refused\n",
+	}
+	d := NewDiagnosticsManager(client, false)
+
+	causes, err := d.AnalyzeCrashLoop(context.Background(), "api-5c8d7-x1", "prod")
+	if err != nil {
+		t.Fatalf("AnalyzeCrashLoop: %v", err)
+	}
+	rc := causes[0]
+	if rc.Category != RootCauseDependency {
+		t.Errorf("category = %s, want dependency", rc.Category)
+	}
+	if len(rc.PreviousLogs) != 3 {
+		t.Errorf("previous logs = %v", rc.PreviousLogs)
+	}
+	if len(rc.RecentChanges) != 2 {
+		t.Fatalf("recent changes = %+v", rc.RecentChanges)
+	}
+	if rc.RecentChanges[0].Kind != "ConfigMap" || rc.RecentChanges[1].Revision != "12" {
+		t.Errorf("changes not ordered oldest first: %+v", rc.RecentChanges)
+	}
+}
+
+func TestClassifyCrashFallbacks(t *testing.T) {
+	tests := []struct {
+		exitCode int
+		logs     string
+		want     string
+	}{
+		{127, "", RootCauseStartupCommand},
+		{137, "", RootCauseKilled},
+		{143, "", RootCauseTerminated},
+		{1, "panic: runtime error: invalid memory address", RootCauseApplicationError},
+		{1, "FATAL: DATABASE_URL environment variable is not set", RootCauseConfiguration},
+		{2, "", RootCauseApplicationError},
+	}
+	for _, tt := range tests {
+		rc := RootCause{LastTermination: &TerminationInfo{ExitCode: tt.exitCode, FinishedAt: time.Now()}}
+		classifyCrash(&rc, tt.logs, nil)
+		if rc.Category != tt.want {
+			t.Errorf("exit %d logs %q: category = %s, want %s", tt.exitCode, tt.logs, rc.Category, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeCrashLoopSkipsHealthyContainers(t *testing.T) {
+	pod := `{"metadata": {"name": "web"}, "status": {"containerStatuses": [{"name": "web", "state": {"running": {}}}]}}`
+	client := &crashMockClient{objects: map[string]string{"pod/web": pod}}
+	causes, err := NewDiagnosticsManager(client, false).AnalyzeCrashLoop(context.Background(), "web", "default")
+	if err != nil {
+		t.Fatalf("AnalyzeCrashLoop: %v", err)
+	}
+	if len(causes) != 0 || len(client.runCalls) != 0 {
+		t.Errorf("healthy pod analyzed: %+v", causes)
+	}
+}
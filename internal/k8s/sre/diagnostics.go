@@ -48,6 +48,7 @@ func (d *DiagnosticsManager) DiagnoseCluster(ctx context.Context) (*DiagnosticRe
 				report.Events = append(report.Events, event)
 			}
 		}
+		d.attachEventTimeline(ctx, report, "")
 	}
 
 	// Generate summary
@@ -94,6 +95,7 @@ func (d *DiagnosticsManager) DiagnoseNamespace(ctx context.Context, namespace st
 	events, err := d.GetEvents(ctx, namespace, "")
 	if err == nil {
 		report.Events = events
+		d.attachEventTimeline(ctx, report, namespace)
 	}
 
 	// Generate summary
@@ -169,6 +171,7 @@ func (d *DiagnosticsManager) diagnosePod(ctx context.Context, name, namespace st
 	events, err := d.GetEvents(ctx, namespace, name)
 	if err == nil {
 		report.Events = events
+		d.attachEventTimeline(ctx, report, namespace)
 	}
 
 	// Get pod logs if there are crash issues
@@ -181,6 +184,7 @@ func (d *DiagnosticsManager) diagnosePod(ctx context.Context, name, namespace st
 			break
 		}
 	}
+	d.attachRootCauses(ctx, report)
 
 	// Generate summary
 	if len(issues) == 0 {
@@ -213,6 +217,7 @@ func (d *DiagnosticsManager) diagnoseDeployment(ctx context.Context, name, names
 	events, err := d.GetEvents(ctx, namespace, name)
 	if err == nil {
 		report.Events = events
+		d.attachEventTimeline(ctx, report, namespace)
 	}
 
 	// If replicas are unavailable, check pods
@@ -223,6 +228,7 @@ func (d *DiagnosticsManager) diagnoseDeployment(ctx context.Context, name, names
 		if err == nil {
 			podIssues := d.detectPodIssuesFromList(podOutput)
 			report.Issues = append(report.Issues, podIssues...)
+			d.attachRootCauses(ctx, report)
 		}
 	}
 
@@ -259,6 +265,7 @@ func (d *DiagnosticsManager) diagnoseNode(ctx context.Context, name string, repo
 	events, err := d.GetEvents(ctx, "", name)
 	if err == nil {
 		report.Events = events
+		d.attachEventTimeline(ctx, report, "")
 	}
 
 	// Generate summary
@@ -292,6 +299,7 @@ func (d *DiagnosticsManager) diagnoseGenericResource(ctx context.Context, resour
 	events, err := d.GetEvents(ctx, namespace, name)
 	if err == nil {
 		report.Events = events
+		d.attachEventTimeline(ctx, report, namespace)
 	}
 
 	if len(issues) == 0 {
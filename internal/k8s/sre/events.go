@@ -0,0 +1,348 @@
+package sre
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxTimelineGroups caps the condensed timeline so a noisy cluster does not
+// bury the report; the most recently active groups are kept.
+const maxTimelineGroups = 15
+
+// Pod names generated by a Deployment look like <deployment>-<rs hash>-<pod
+// suffix>; ReplicaSets drop the final segment.
+var (
+	deploymentPodNamePattern = regexp.MustCompile(`^(.+)-[a-z0-9]{6,10}-[a-z0-9]{5}$`)
+	replicaSetNamePattern    = regexp.MustCompile(`^(.+)-[a-z0-9]{6,10}$`)
+)
+
+// CorrelateEvents collapses Warning events into groups keyed by owning
+// workload and reason. owners maps "namespace/Kind/name" of an involved
+// object to its workload ("Deployment/web"); objects missing from the map
+// fall back to a name-based guess. Groups come back oldest first and capped
+// at maxTimelineGroups.
+func CorrelateEvents(events []EventInfo, owners map[string]string) []EventGroup {
+	groups := map[string]*EventGroup{}
+	var order []string
+
+	for _, event := range events {
+		if event.Type != "Warning" {
+			continue
+		}
+		obj := event.InvolvedObject
+		workload := owners[eventObjectKey(obj.Namespace, obj.Kind, obj.Name)]
+		if workload == "" {
+			workload = guessWorkload(obj.Kind, obj.Name)
+		}
+
+		key := obj.Namespace + "|" + workload + "|" + event.Reason
+		group, ok := groups[key]
+		if !ok {
+			group = &EventGroup{
+				Type:      event.Type,
+				Reason:    event.Reason,
+				Workload:  workload,
+				Namespace: obj.Namespace,
+			}
+			groups[key] = group
+			order = append(order, key)
+		}
+
+		count := event.Count
+		if count < 1 {
+			count = 1
+		}
+		group.Count += count
+
+		first, last := event.FirstTimestamp, event.LastTimestamp
+		if first.IsZero() {
+			first = last
+		}
+		if last.IsZero() {
+			last = first
+		}
+		if !first.IsZero() && (group.FirstSeen.IsZero() || first.Before(group.FirstSeen)) {
+			group.FirstSeen = first
+		}
+		if group.Message == "" || !last.Before(group.LastSeen) {
+			group.LastSeen = last
+			group.Message = event.Message
+		}
+
+		object := obj.Kind + "/" + obj.Name
+		if object != workload && !containsStr(group.Objects, object) {
+			group.Objects = append(group.Objects, object)
+		}
+	}
+
+	timeline := make([]EventGroup, 0, len(order))
+	for _, key := range order {
+		timeline = append(timeline, *groups[key])
+	}
+
+	if len(timeline) > maxTimelineGroups {
+		sort.SliceStable(timeline, func(i, j int) bool {
+			return timeline[i].LastSeen.After(timeline[j].LastSeen)
+		})
+		timeline = timeline[:maxTimelineGroups]
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].FirstSeen.Before(timeline[j].FirstSeen)
+	})
+	return timeline
+}
+
+// FormatTimelineEntry renders one group as a single timeline line
+func FormatTimelineEntry(g EventGroup) string {
+	var sb strings.Builder
+	if !g.FirstSeen.IsZero() {
+		sb.WriteString(g.FirstSeen.Format("15:04:05"))
+		if !g.LastSeen.Equal(g.FirstSeen) {
+			sb.WriteString("-" + g.LastSeen.Format("15:04:05"))
+		}
+		sb.WriteString(" ")
+	}
+	target := g.Workload
+	if g.Namespace != "" {
+		target = g.Namespace + "/" + target
+	}
+	sb.WriteString(fmt.Sprintf("%s %s x%d: %s", target, g.Reason, g.Count, g.Message))
+	if len(g.Objects) > 1 {
+		sb.WriteString(fmt.Sprintf(" (%d objects)", len(g.Objects)))
+	}
+	return sb.String()
+}
+
+// eventRemediationSteps turns timeline groups into remediation suggestions,
+// one per workload and reason, most frequent first
+func eventRemediationSteps(timeline []EventGroup) []RemediationStep {
+	sorted := append([]EventGroup(nil), timeline...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Count > sorted[j].Count })
+
+	var steps []RemediationStep
+	for _, g := range sorted {
+		step, ok := remediationForEventGroup(g)
+		if !ok {
+			continue
+		}
+		step.Description = fmt.Sprintf("%s (%s %d times, first %s, last %s)", step.Description, g.Reason, g.Count,
+			formatEventTime(g.FirstSeen), formatEventTime(g.LastSeen))
+		step.Order = len(steps) + 1
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+func remediationForEventGroup(g EventGroup) (RemediationStep, bool) {
+	kind, name := splitWorkload(g.Workload)
+	resource := strings.ToLower(kind) + "/" + name
+	nsArgs := []string{}
+	if g.Namespace != "" {
+		nsArgs = []string{"-n", g.Namespace}
+	}
+	message := strings.ToLower(g.Message)
+
+	switch {
+	case g.Reason == "BackOff" && strings.Contains(message, "pull"),
+		g.Reason == "Failed" && strings.Contains(message, "pull"),
+		g.Reason == "ErrImagePull", g.Reason == "ImagePullBackOff", g.Reason == "InspectFailed":
+		return RemediationStep{
+			Action:      "Fix image reference for " + g.Workload,
+			Description: "Image cannot be pulled; verify the tag exists and pull secrets are set",
+			Command:     "kubectl",
+			Args:        append([]string{"get", resource, "-o", "jsonpath={.spec.template.spec.containers[*].image}"}, nsArgs...),
+			Risk:        "low",
+		}, true
+	case g.Reason == "BackOff":
+		return RemediationStep{
+			Action:      "Inspect crash logs for " + g.Workload,
+			Description: "Containers are restarting; read the previous container's logs",
+			Command:     "kubectl",
+			Args:        append([]string{"logs", resource, "--previous", "--tail=100"}, nsArgs...),
+			Risk:        "low",
+		}, true
+	case g.Reason == "OOMKilling" || strings.Contains(message, "oomkilled"):
+		return RemediationStep{
+			Action:      "Raise memory limits for " + g.Workload,
+			Description: "Containers are being OOM killed; compare usage with limits",
+			Command:     "kubectl",
+			Args:        append([]string{"top", "pods", "--containers"}, nsArgs...),
+			Risk:        "medium",
+		}, true
+	case g.Reason == "Unhealthy":
+		return RemediationStep{
+			Action:      "Review probes for " + g.Workload,
+			Description: "Liveness or readiness probes are failing; check probe paths, ports, and timeouts",
+			Command:     "kubectl",
+			Args:        append([]string{"describe", resource}, nsArgs...),
+			Risk:        "low",
+		}, true
+	case g.Reason == "FailedScheduling":
+		return RemediationStep{
+			Action:      "Resolve scheduling for " + g.Workload,
+			Description: "Pods cannot be placed; check node capacity, taints, and affinity",
+			Command:     "kubectl",
+			Args:        []string{"describe", "nodes"},
+			Risk:        "low",
+		}, true
+	case g.Reason == "FailedMount" || g.Reason == "FailedAttachVolume":
+		return RemediationStep{
+			Action:      "Check volumes for " + g.Workload,
+			Description: "Volumes fail to mount; verify the PVC is bound and the node can attach it",
+			Command:     "kubectl",
+			Args:        append([]string{"get", "pvc"}, nsArgs...),
+			Risk:        "low",
+		}, true
+	case g.Reason == "FailedCreate":
+		return RemediationStep{
+			Action:      "Check quotas for " + g.Workload,
+			Description: "The controller cannot create pods; check ResourceQuota, LimitRange, and admission webhooks",
+			Command:     "kubectl",
+			Args:        append([]string{"get", "resourcequota,limitrange"}, nsArgs...),
+			Risk:        "low",
+		}, true
+	case g.Reason == "Evicted":
+		return RemediationStep{
+			Action:      "Investigate evictions for " + g.Workload,
+			Description: "Pods are evicted under node pressure; check node conditions and requests",
+			Command:     "kubectl",
+			Args:        []string{"get", "nodes", "-o", "wide"},
+			Risk:        "low",
+		}, true
+	}
+	return RemediationStep{}, false
+}
+
+// resolveEventOwners maps pods and ReplicaSets in the events to their owning
+// workload using ownerReferences. Lookup failures leave the map partial;
+// CorrelateEvents then falls back to name-based guesses.
+func (d *DiagnosticsManager) resolveEventOwners(ctx context.Context, namespace string, events []EventInfo) map[string]string {
+	owners := map[string]string{}
+	needed := false
+	for _, e := range events {
+		if e.Type == "Warning" && (e.InvolvedObject.Kind == "Pod" || e.InvolvedObject.Kind == "ReplicaSet") {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return owners
+	}
+
+	scope := []string{"-A"}
+	if namespace != "" {
+		scope = []string{"-n", namespace}
+	}
+
+	// ReplicaSet -> Deployment first so pods can chain through it
+	rsOwners := map[string]string{}
+	if data, err := d.client.RunJSON(ctx, append([]string{"get", "replicasets"}, scope...)...); err == nil {
+		for key, owner := range parseOwnerReferences(data, "ReplicaSet") {
+			rsOwners[key] = owner
+			owners[key] = owner
+		}
+	} else {
+		logger.Debugf("replicaset owner lookup failed: %v", err)
+	}
+
+	if data, err := d.client.RunJSON(ctx, append([]string{"get", "pods"}, scope...)...); err == nil {
+		for key, owner := range parseOwnerReferences(data, "Pod") {
+			ns := strings.SplitN(key, "/", 2)[0]
+			if deployment, ok := rsOwners[eventObjectKey(ns, "ReplicaSet", strings.TrimPrefix(owner, "ReplicaSet/"))]; ok {
+				owner = deployment
+			}
+			owners[key] = owner
+		}
+	} else {
+		logger.Debugf("pod owner lookup failed: %v", err)
+	}
+
+	return owners
+}
+
+// parseOwnerReferences returns "namespace/kind/name" -> "OwnerKind/owner"
+// for every item with a controller owner
+func parseOwnerReferences(data []byte, kind string) map[string]string {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name            string `json:"name"`
+				Namespace       string `json:"namespace"`
+				OwnerReferences []struct {
+					Kind       string `json:"kind"`
+					Name       string `json:"name"`
+					Controller bool   `json:"controller"`
+				} `json:"ownerReferences"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	owners := map[string]string{}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return owners
+	}
+	for _, item := range list.Items {
+		for _, ref := range item.Metadata.OwnerReferences {
+			if ref.Controller {
+				owners[eventObjectKey(item.Metadata.Namespace, kind, item.Metadata.Name)] = ref.Kind + "/" + ref.Name
+				break
+			}
+		}
+	}
+	return owners
+}
+
+// attachEventTimeline correlates the report's events and derives
+// remediation suggestions from the timeline
+func (d *DiagnosticsManager) attachEventTimeline(ctx context.Context, report *DiagnosticReport, namespace string) {
+	if len(report.Events) == 0 {
+		return
+	}
+	report.Timeline = CorrelateEvents(report.Events, d.resolveEventOwners(ctx, namespace, report.Events))
+	report.Remediation = eventRemediationSteps(report.Timeline)
+}
+
+func guessWorkload(kind, name string) string {
+	switch kind {
+	case "Pod":
+		if m := deploymentPodNamePattern.FindStringSubmatch(name); m != nil {
+			return "Deployment/" + m[1]
+		}
+	case "ReplicaSet":
+		if m := replicaSetNamePattern.FindStringSubmatch(name); m != nil {
+			return "Deployment/" + m[1]
+		}
+	}
+	return kind + "/" + name
+}
+
+func splitWorkload(workload string) (string, string) {
+	if i := strings.Index(workload, "/"); i >= 0 {
+		return workload[:i], workload[i+1:]
+	}
+	return "", workload
+}
+
+func eventObjectKey(namespace, kind, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+func formatEventTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Format("15:04:05")
+}
+
+func containsStr(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
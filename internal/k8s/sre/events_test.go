@@ -0,0 +1,120 @@
+package sre
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func warningEvent(kind, name, namespace, reason, message string, count int, first, last time.Time) EventInfo {
+	e := EventInfo{Type: "Warning", Reason: reason, Message: message, Count: count, FirstTimestamp: first, LastTimestamp: last}
+	e.InvolvedObject.Kind = kind
+	e.InvolvedObject.Name = name
+	e.InvolvedObject.Namespace = namespace
+	return e
+}
+
+func TestCorrelateEvents(t *testing.T) {
+	base := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	events := []EventInfo{
+		warningEvent("Pod", "web-7d9f8c6b5-abcde", "prod", "BackOff", "Back-off restarting failed container", 4, base, base.Add(5*time.Minute)),
+		warningEvent("Pod", "web-7d9f8c6b5-fghij", "prod", "BackOff", "Back-off restarting failed container app", 3, base.Add(time.Minute), base.Add(8*time.Minute)),
+		warningEvent("Pod", "worker-0", "prod", "FailedScheduling", "0/3 nodes are available", 1, base.Add(-time.Hour), base.Add(-time.Hour)),
+		{Type: "Normal", Reason: "Pulled", Message: "ignored"},
+	}
+	owners := map[string]string{"prod/Pod/worker-0": "StatefulSet/worker"}
+
+	timeline := CorrelateEvents(events, owners)
+	if len(timeline) != 2 {
+		t.Fatalf("got %d groups, want 2: %+v", len(timeline), timeline)
+	}
+
+	// Oldest first
+	if timeline[0].Workload != "StatefulSet/worker" || timeline[0].Reason != "FailedScheduling" {
+		t.Errorf("first group = %+v", timeline[0])
+	}
+
+	web := timeline[1]
+	if web.Workload != "Deployment/web" {
+		t.Errorf("pod hash suffix not collapsed to deployment: %s", web.Workload)
+	}
+	if web.Count != 7 || len(web.Objects) != 2 {
+		t.Errorf("web group count=%d objects=%v, want 7 and 2 pods", web.Count, web.Objects)
+	}
+	if !web.FirstSeen.Equal(base) || !web.LastSeen.Equal(base.Add(8*time.Minute)) {
+		t.Errorf("web first/last = %s/%s", web.FirstSeen, web.LastSeen)
+	}
+	if web.Message != "Back-off restarting failed container app" {
+		t.Errorf("expected latest message, got %q", web.Message)
+	}
+}
+
+func TestCorrelateEventsCapsTimeline(t *testing.T) {
+	base := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	var events []EventInfo
+	for i := 0; i < maxTimelineGroups+5; i++ {
+		ts := base.Add(time.Duration(i) * time.Minute)
+		events = append(events, warningEvent("Node", strings.Repeat("n", i+1), "", "NodeNotReady", "not ready", 1, ts, ts))
+	}
+
+	timeline := CorrelateEvents(events, nil)
+	if len(timeline) != maxTimelineGroups {
+		t.Fatalf("got %d groups, want %d", len(timeline), maxTimelineGroups)
+	}
+	// The oldest groups are dropped, the rest stay chronological
+	if !timeline[0].FirstSeen.Equal(base.Add(5 * time.Minute)) {
+		t.Errorf("timeline starts at %s", timeline[0].FirstSeen)
+	}
+}
+
+func TestEventRemediationSteps(t *testing.T) {
+	base := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	timeline := []EventGroup{
+		{Reason: "Unhealthy", Workload: "Deployment/api", Namespace: "prod", Count: 2, FirstSeen: base, LastSeen: base},
+		{Reason: "BackOff", Message: "Back-off pulling image \"api:v9\"", Workload: "Deployment/api", Namespace: "prod", Count: 12, FirstSeen: base, LastSeen: base},
+		{Reason: "SomethingElse", Workload: "Pod/x", Count: 50},
+	}
+
+	steps := eventRemediationSteps(timeline)
+	if len(steps) != 2 {
+		t.Fatalf("got %d steps, want 2: %+v", len(steps), steps)
+	}
+	if !strings.HasPrefix(steps[0].Action, "Fix image reference") || steps[0].Order != 1 {
+		t.Errorf("most frequent group should come first as an image fix: %+v", steps[0])
+	}
+	if !strings.Contains(steps[0].Description, "BackOff 12 times") {
+		t.Errorf("description missing counts: %s", steps[0].Description)
+	}
+	if strings.Join(steps[1].Args, " ") != "describe deployment/api -n prod" {
+		t.Errorf("probe step args = %v", steps[1].Args)
+	}
+}
+
+func TestResolveEventOwnersChainsReplicaSets(t *testing.T) {
+	client := &ownerMockClient{
+		replicaSets: `{"items":[{"metadata":{"name":"api-5c8d7","namespace":"prod","ownerReferences":[{"kind":"Deployment","name":"api","controller":true}]}}]}`,
+		pods:        `{"items":[{"metadata":{"name":"api-5c8d7-x1","namespace":"prod","ownerReferences":[{"kind":"ReplicaSet","name":"api-5c8d7","controller":true}]}}]}`,
+	}
+	d := NewDiagnosticsManager(client, false)
+	events := []EventInfo{warningEvent("Pod", "api-5c8d7-x1", "prod", "BackOff", "restarting", 1, time.Time{}, time.Time{})}
+
+	owners := d.resolveEventOwners(context.Background(), "prod", events)
+	if owners["prod/Pod/api-5c8d7-x1"] != "Deployment/api" {
+		t.Errorf("owners = %v", owners)
+	}
+}
+
+// ownerMockClient answers replicaset and pod listings separately
+type ownerMockClient struct {
+	mockK8sClient
+	replicaSets string
+	pods        string
+}
+
+func (m *ownerMockClient) RunJSON(ctx context.Context, args ...string) ([]byte, error) {
+	if args[1] == "replicasets" {
+		return []byte(m.replicaSets), nil
+	}
+	return []byte(m.pods), nil
+}
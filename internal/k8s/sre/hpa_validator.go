@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 )
@@ -59,17 +58,13 @@ func (v *HPAValidator) Validate(ctx context.Context) (*HPAValidationReport, erro
 	keda, err := v.kedaInstalled(ctx)
 	if err != nil {
 		appendNote(&report.Notes, fmt.Sprintf("keda detection failed: %v", err))
-		if v.debug {
-			fmt.Fprintln(os.Stderr, "[hpa-validator] keda detection failed:", err)
-		}
+		logger.Debug("keda detection failed", "error", err)
 	} else if keda {
 		report.KEDAInstalled = true
 		sos, err := v.listScaledObjects(ctx)
 		if err != nil {
 			appendNote(&report.Notes, fmt.Sprintf("keda detected but scaledobjects fetch failed: %v", err))
-			if v.debug {
-				fmt.Fprintln(os.Stderr, "[hpa-validator] keda detected but scaledobjects fetch failed:", err)
-			}
+			logger.Debug("keda detected but scaledobjects fetch failed", "error", err)
 		} else {
 			report.ScaledObjectsScanned = len(sos)
 			for _, s := range sos {
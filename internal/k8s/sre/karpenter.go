@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 )
@@ -113,11 +112,7 @@ func (d *KarpenterDetector) ListNodePools(ctx context.Context) ([]NodePoolSummar
 	for _, item := range list.Items {
 		summary, err := parseNodePool(item)
 		if err != nil {
-			if d.debug {
-				// stderr so we don't corrupt stdout when the caller is consuming
-				// `-o json` output.
-				fmt.Fprintf(os.Stderr, "[karpenter] skipping unparseable NodePool: %v\n", err)
-			}
+			logger.Debug("skipping unparseable NodePool", "error", err)
 			continue
 		}
 		pools = append(pools, summary)
@@ -147,9 +142,7 @@ func (d *KarpenterDetector) ListNodeClaims(ctx context.Context) ([]NodeClaimSumm
 	for _, item := range list.Items {
 		summary, err := parseNodeClaim(item)
 		if err != nil {
-			if d.debug {
-				fmt.Fprintf(os.Stderr, "[karpenter] skipping unparseable NodeClaim: %v\n", err)
-			}
+			logger.Debug("skipping unparseable NodeClaim", "error", err)
 			continue
 		}
 		claims = append(claims, summary)
@@ -5,13 +5,18 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("k8s.sre")
+
 // SubAgent handles SRE related queries for diagnostics, health checks, and remediation
 type SubAgent struct {
 	client      K8sClient
 	diagnostics *DiagnosticsManager
 	health      *HealthChecker
+	errors      ErrorTracker
 	debug       bool
 }
 
@@ -25,16 +30,19 @@ func NewSubAgent(client K8sClient, debug bool) *SubAgent {
 	}
 }
 
+// SetErrorTracker has diagnoses of a named resource include the errors
+// tracker has seen from it
+func (s *SubAgent) SetErrorTracker(tracker ErrorTracker) {
+	s.errors = tracker
+}
+
 // HandleQuery processes an SRE related query and returns a response
 func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
 	query = strings.ToLower(query)
 
 	analysis := s.analyzeQuery(query)
 
-	if s.debug {
-		fmt.Printf("[sre] analysis: type=%s, op=%s, name=%s, ns=%s, readonly=%v\n",
-			analysis.ResourceType, analysis.Operation, analysis.ResourceName, analysis.Namespace, analysis.IsReadOnly)
-	}
+	logger.Debugf("analysis: type=%s, op=%s, name=%s, ns=%s, readonly=%v", analysis.ResourceType, analysis.Operation, analysis.ResourceName, analysis.Namespace, analysis.IsReadOnly)
 
 	// Use options namespace as fallback if not extracted from query
 	if analysis.Namespace == "" && opts.Namespace != "" {
@@ -203,9 +211,7 @@ func (s *SubAgent) isCommonWord(word string) bool {
 
 // handleHealthCheck performs a health check
 func (s *SubAgent) handleHealthCheck(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[sre] performing health check\n")
-	}
+	logger.Debug("performing health check")
 
 	// Determine scope of health check
 	if analysis.ResourceName != "" && analysis.ResourceType != "" {
@@ -251,9 +257,7 @@ func (s *SubAgent) handleHealthCheck(ctx context.Context, query string, analysis
 
 // handleDiagnose performs diagnostic analysis
 func (s *SubAgent) handleDiagnose(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[sre] performing diagnostic analysis\n")
-	}
+	logger.Debug("performing diagnostic analysis")
 
 	var report *DiagnosticReport
 	var err error
@@ -272,6 +276,7 @@ func (s *SubAgent) handleDiagnose(ctx context.Context, query string, analysis Qu
 	if err != nil {
 		return nil, fmt.Errorf("diagnostic analysis failed: %w", err)
 	}
+	s.attachTrackedErrors(ctx, report)
 
 	return &Response{
 		Type:    ResponseTypeReport,
@@ -280,11 +285,23 @@ func (s *SubAgent) handleDiagnose(ctx context.Context, query string, analysis Qu
 	}, nil
 }
 
+// attachTrackedErrors adds the error tracker's errors for a resource
+// report. A tracker failure only costs the report that section.
+func (s *SubAgent) attachTrackedErrors(ctx context.Context, report *DiagnosticReport) {
+	if s.errors == nil || report == nil || report.ResourceName == "" || report.ResourceType == "node" {
+		return
+	}
+	tracked, err := s.errors.RelatedErrors(ctx, report.ResourceType, report.ResourceName, report.Namespace)
+	if err != nil {
+		logger.Debugf("error tracker lookup failed: %v", err)
+		return
+	}
+	report.TrackedErrors = tracked
+}
+
 // handleLogs retrieves and analyzes logs
 func (s *SubAgent) handleLogs(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[sre] retrieving logs\n")
-	}
+	logger.Debug("retrieving logs")
 
 	if analysis.ResourceName == "" {
 		return nil, fmt.Errorf("please specify a pod name for log analysis")
@@ -323,9 +340,7 @@ func (s *SubAgent) handleLogs(ctx context.Context, query string, analysis QueryA
 
 // handleEvents retrieves and analyzes events
 func (s *SubAgent) handleEvents(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[sre] retrieving events\n")
-	}
+	logger.Debug("retrieving events")
 
 	namespace := analysis.Namespace
 	if opts.AllNamespaces {
@@ -359,9 +374,7 @@ func (s *SubAgent) handleEvents(ctx context.Context, query string, analysis Quer
 
 // handleIssues detects and reports issues
 func (s *SubAgent) handleIssues(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[sre] detecting issues\n")
-	}
+	logger.Debug("detecting issues")
 
 	var issues []Issue
 	var err error
@@ -404,9 +417,7 @@ func (s *SubAgent) handleIssues(ctx context.Context, query string, analysis Quer
 
 // handleWhy analyzes why a resource is in a particular state
 func (s *SubAgent) handleWhy(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[sre] analyzing root cause\n")
-	}
+	logger.Debug("analyzing root cause")
 
 	if analysis.ResourceName == "" {
 		return nil, fmt.Errorf("please specify a resource name to analyze")
@@ -426,6 +437,7 @@ func (s *SubAgent) handleWhy(ctx context.Context, query string, analysis QueryAn
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze resource: %w", err)
 	}
+	s.attachTrackedErrors(ctx, report)
 
 	return &Response{
 		Type:    ResponseTypeReport,
@@ -436,9 +448,7 @@ func (s *SubAgent) handleWhy(ctx context.Context, query string, analysis QueryAn
 
 // handleFix generates a remediation plan
 func (s *SubAgent) handleFix(ctx context.Context, query string, analysis QueryAnalysis, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[sre] generating remediation plan\n")
-	}
+	logger.Debug("generating remediation plan")
 
 	if analysis.ResourceName == "" {
 		return nil, fmt.Errorf("please specify a resource name to fix")
@@ -479,7 +489,7 @@ func (s *SubAgent) generateRemediationPlan(report *DiagnosticReport) *SREPlan {
 		Notes:   []string{},
 	}
 
-	if len(report.Issues) == 0 {
+	if len(report.Issues) == 0 && len(report.Remediation) == 0 {
 		plan.Summary = "No issues found, no remediation needed"
 		return plan
 	}
@@ -494,6 +504,21 @@ func (s *SubAgent) generateRemediationPlan(report *DiagnosticReport) *SREPlan {
 		}
 	}
 
+	// Event-derived steps carry the workload and how often the warning
+	// fired, so they follow the issue steps rather than replace them
+	for _, step := range report.Remediation {
+		step.Order = order
+		plan.Steps = append(plan.Steps, step)
+		order++
+	}
+	for _, rc := range report.RootCauses {
+		plan.Notes = append(plan.Notes, fmt.Sprintf("Root cause for %s/%s (%s, %s confidence): %s",
+			rc.Pod, rc.Container, rc.Category, rc.Confidence, rc.Summary))
+	}
+	for _, group := range report.Timeline {
+		plan.Notes = append(plan.Notes, FormatTimelineEntry(group))
+	}
+
 	if len(plan.Steps) == 0 {
 		plan.Notes = append(plan.Notes, "No automated remediation available for detected issues")
 		plan.Notes = append(plan.Notes, "Manual investigation recommended")
@@ -2,6 +2,8 @@ package sre
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -792,3 +794,43 @@ func containsSubstringHelper(s, substr string) bool {
 	}
 	return false
 }
+
+// fakeErrorTracker returns fixed errors and records what it was asked for
+type fakeErrorTracker struct {
+	errs   []TrackedError
+	err    error
+	lookup []string
+}
+
+func (f *fakeErrorTracker) RelatedErrors(ctx context.Context, resourceType, name, namespace string) ([]TrackedError, error) {
+	f.lookup = []string{resourceType, name, namespace}
+	return f.errs, f.err
+}
+
+func TestHandleWhyAttachesTrackedErrors(t *testing.T) {
+	tracker := &fakeErrorTracker{errs: []TrackedError{{Source: "sentry", ID: "BACKEND-42", Title: "TypeError"}}}
+	agent := NewSubAgent(&mockK8sClient{runOutput: "Name: web"}, false)
+	agent.SetErrorTracker(tracker)
+
+	analysis := QueryAnalysis{ResourceType: ResourceStatefulSet, ResourceName: "web", Namespace: "shop"}
+	resp, err := agent.handleWhy(context.Background(), "why is statefulset web failing", analysis, QueryOptions{})
+	if err != nil {
+		t.Fatalf("handleWhy: %v", err)
+	}
+	if got := resp.Report.TrackedErrors; len(got) != 1 || got[0].ID != "BACKEND-42" {
+		t.Errorf("TrackedErrors = %+v", got)
+	}
+	if want := []string{"statefulset", "web", "shop"}; strings.Join(tracker.lookup, "/") != strings.Join(want, "/") {
+		t.Errorf("lookup = %v, want %v", tracker.lookup, want)
+	}
+
+	// A failing tracker leaves the diagnosis intact
+	tracker.err = errors.New("sentry unavailable")
+	resp, err = agent.handleWhy(context.Background(), "why is statefulset web failing", analysis, QueryOptions{})
+	if err != nil {
+		t.Fatalf("handleWhy with failing tracker: %v", err)
+	}
+	if resp.Report.TrackedErrors != nil {
+		t.Errorf("TrackedErrors = %+v, want none", resp.Report.TrackedErrors)
+	}
+}
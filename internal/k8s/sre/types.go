@@ -182,6 +182,55 @@ type EventInfo struct {
 	} `json:"involved_object"`
 }
 
+// EventGroup is a run of repeated Warning events for one workload and reason
+type EventGroup struct {
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`  // most recent message
+	Workload  string    `json:"workload"` // owning workload, e.g. Deployment/web
+	Namespace string    `json:"namespace,omitempty"`
+	Objects   []string  `json:"objects,omitempty"` // involved objects other than the workload
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// RootCause is the crash-loop analyzer's verdict for one container
+type RootCause struct {
+	Pod             string           `json:"pod"`
+	Namespace       string           `json:"namespace"`
+	Container       string           `json:"container"`
+	Category        string           `json:"category"`   // oom_killed, killed, terminated, startup_command, configuration, dependency, application_error
+	Confidence      string           `json:"confidence"` // high, medium, low
+	Summary         string           `json:"summary"`
+	RestartCount    int              `json:"restart_count"`
+	LastTermination *TerminationInfo `json:"last_termination,omitempty"`
+	Requests        ResourceList     `json:"requests"`
+	Limits          ResourceList     `json:"limits"`
+	PreviousLogs    []string         `json:"previous_logs,omitempty"` // tail of the crashed container's log
+	RecentChanges   []ConfigChange   `json:"recent_changes,omitempty"`
+	Evidence        []string         `json:"evidence,omitempty"`
+}
+
+// TerminationInfo is a container's last terminated state
+type TerminationInfo struct {
+	Reason     string    `json:"reason"`
+	ExitCode   int       `json:"exit_code"`
+	Signal     int       `json:"signal,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// ConfigChange is a rollout or ConfigMap/Secret edit affecting a pod
+type ConfigChange struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Revision  string    `json:"revision,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
 // HealthCheckResult represents the result of a health check
 type HealthCheckResult struct {
 	Healthy     bool      `json:"healthy"`
@@ -225,8 +274,31 @@ type DiagnosticReport struct {
 	Summary      string            `json:"summary"`
 	Issues       []Issue           `json:"issues"`
 	Events       []EventInfo       `json:"events,omitempty"`
+	Timeline     []EventGroup      `json:"timeline,omitempty"`
+	RootCauses   []RootCause       `json:"root_causes,omitempty"`
 	Logs         []LogEntry        `json:"logs,omitempty"`
 	Remediation  []RemediationStep `json:"remediation,omitempty"`
+	// TrackedErrors are application errors an ErrorTracker matched to the
+	// resource
+	TrackedErrors []TrackedError `json:"tracked_errors,omitempty"`
+}
+
+// ErrorTracker looks up the application errors an error tracker such as
+// Sentry has grouped for a workload, so a diagnosis can show them next to
+// what the cluster reports
+type ErrorTracker interface {
+	RelatedErrors(ctx context.Context, resourceType, name, namespace string) ([]TrackedError, error)
+}
+
+// TrackedError is one grouped error from an ErrorTracker
+type TrackedError struct {
+	Source   string    `json:"source"` // e.g. sentry
+	ID       string    `json:"id"`
+	Title    string    `json:"title"`
+	Events   string    `json:"events,omitempty"`
+	Users    int       `json:"users,omitempty"`
+	LastSeen time.Time `json:"last_seen"`
+	Link     string    `json:"link,omitempty"`
 }
 
 // LogEntry represents a log line with metadata
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"sort"
 	"strings"
 	"time"
@@ -82,9 +81,7 @@ func (a *Auditor) Audit(ctx context.Context) (*AuditReport, error) {
 		// can't detect orphaned PVCs without it. Surface in Notes so the
 		// operator knows the orphan signal was suppressed.
 		report.Notes = fmt.Sprintf("pod scan failed, orphaned-PVC detection disabled: %v", err)
-		if a.debug {
-			fmt.Fprintln(os.Stderr, "[storage-audit] pod scan failed, orphaned-PVC detection disabled:", err)
-		}
+		logger.Debug("pod scan failed, orphaned-PVC detection disabled", "error", err)
 	}
 	report.PodsScanned = podsScanned
 
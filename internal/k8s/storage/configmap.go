@@ -247,9 +247,7 @@ func (m *ConfigMapManager) parseConfigMapList(data []byte) ([]ConfigMapInfo, err
 	for _, item := range list.Items {
 		cm, err := m.parseConfigMap(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[storage] failed to parse ConfigMap: %v\n", err)
-			}
+			logger.Debugf("failed to parse ConfigMap: %v", err)
 			continue
 		}
 		cms = append(cms, *cm)
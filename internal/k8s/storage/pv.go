@@ -309,9 +309,7 @@ func (m *PVManager) parsePVList(data []byte) ([]PVInfo, error) {
 	for _, item := range list.Items {
 		pv, err := m.parsePV(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[storage] failed to parse PV: %v\n", err)
-			}
+			logger.Debugf("failed to parse PV: %v", err)
 			continue
 		}
 		pvs = append(pvs, *pv)
@@ -417,9 +415,7 @@ func (m *PVManager) parseStorageClassList(data []byte) ([]StorageClassInfo, erro
 	for _, item := range list.Items {
 		sc, err := m.parseStorageClass(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[storage] failed to parse StorageClass: %v\n", err)
-			}
+			logger.Debugf("failed to parse StorageClass: %v", err)
 			continue
 		}
 		scs = append(scs, *sc)
@@ -192,6 +192,13 @@ func (m *PVCManager) generatePVCManifest(opts CreatePVCOptions) string {
 		sb.WriteString(fmt.Sprintf("  volumeName: %s\n", opts.VolumeName))
 	}
 
+	if opts.DataSourceSnapshot != "" {
+		sb.WriteString("  dataSource:\n")
+		sb.WriteString(fmt.Sprintf("    name: %s\n", opts.DataSourceSnapshot))
+		sb.WriteString("    kind: VolumeSnapshot\n")
+		sb.WriteString(fmt.Sprintf("    apiGroup: %s\n", snapshotAPIGroup))
+	}
+
 	if len(opts.Selector) > 0 {
 		sb.WriteString("  selector:\n")
 		sb.WriteString("    matchLabels:\n")
@@ -217,9 +224,7 @@ func (m *PVCManager) parsePVCList(data []byte) ([]PVCInfo, error) {
 	for _, item := range list.Items {
 		pvc, err := m.parsePVC(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[storage] failed to parse PVC: %v\n", err)
-			}
+			logger.Debugf("failed to parse PVC: %v", err)
 			continue
 		}
 		pvcs = append(pvcs, *pvc)
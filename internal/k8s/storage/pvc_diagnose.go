@@ -0,0 +1,512 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var (
+	pendingWords      = []string{"pending", "not bound", "unbound", "stuck", "won't bind", "not binding", "isn't binding"}
+	claimWords        = []string{"pvc", "persistentvolumeclaim", "persistent volume claim", "volume claim"}
+	pendingPVCPattern = regexp.MustCompile(`\b(?:pvc|persistentvolumeclaim|claim)[\s/]+(?:named?\s+)?([a-z0-9][a-z0-9.-]*[a-z0-9])`)
+	notPVCNames       = map[string]bool{"is": true, "for": true, "in": true, "that": true, "which": true, "pending": true,
+		"stuck": true, "of": true, "not": true, "still": true, "are": true, "was": true, "has": true}
+)
+
+// ParsePendingQuery reads questions such as "why is my PVC pending" or "pvc
+// data is stuck pending". name is empty when the question names no claim.
+func ParsePendingQuery(query string) (name string, ok bool) {
+	lower := strings.ToLower(query)
+	if !containsAny(lower, claimWords) || !containsAny(lower, pendingWords) {
+		return "", false
+	}
+	for _, m := range pendingPVCPattern.FindAllStringSubmatch(lower, -1) {
+		if !notPVCNames[m[1]] {
+			return m[1], true
+		}
+	}
+	return "", true
+}
+
+// PVCCause is one reason a claim is not bound
+type PVCCause struct {
+	Reason string `json:"reason"`
+	Detail string `json:"detail"`
+}
+
+// PVCDiagnosis explains why a PersistentVolumeClaim is not bound and holds
+// the steps that would fix it
+type PVCDiagnosis struct {
+	PVC          string        `json:"pvc"`
+	Namespace    string        `json:"namespace"`
+	Status       string        `json:"status"`
+	StorageClass string        `json:"storageClass,omitempty"`
+	Causes       []PVCCause    `json:"causes,omitempty"`
+	Fixes        []StorageStep `json:"fixes,omitempty"`
+	Notes        []string      `json:"notes,omitempty"`
+}
+
+func (d *PVCDiagnosis) addCause(reason, detail string) {
+	for _, c := range d.Causes {
+		if c.Reason == reason {
+			return
+		}
+	}
+	d.Causes = append(d.Causes, PVCCause{Reason: reason, Detail: detail})
+}
+
+// String renders the diagnosis for the terminal
+func (d *PVCDiagnosis) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "PVC %s/%s is %s", d.Namespace, d.PVC, d.Status)
+	if d.StorageClass != "" {
+		fmt.Fprintf(&sb, " (StorageClass %s)", d.StorageClass)
+	}
+	sb.WriteString("\n")
+	for _, c := range d.Causes {
+		fmt.Fprintf(&sb, "  - %s: %s\n", c.Reason, c.Detail)
+	}
+	for _, note := range d.Notes {
+		fmt.Fprintf(&sb, "  Note: %s\n", note)
+	}
+	return sb.String()
+}
+
+// rawPVC keeps the fields diagnosis needs that PVCInfo folds away, such as
+// an absent storageClassName versus an empty one
+type rawPVC struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		AccessModes      []string `json:"accessModes"`
+		StorageClassName *string  `json:"storageClassName"`
+		VolumeMode       string   `json:"volumeMode"`
+		VolumeName       string   `json:"volumeName"`
+		Resources        struct {
+			Requests struct {
+				Storage string `json:"storage"`
+			} `json:"requests"`
+		} `json:"resources"`
+	} `json:"spec"`
+	Status struct {
+		Phase string `json:"phase"`
+	} `json:"status"`
+}
+
+// rawStorageClass keeps the fields diagnosis needs from a StorageClass
+type rawStorageClass struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Provisioner       string `json:"provisioner"`
+	VolumeBindingMode string `json:"volumeBindingMode"`
+	AllowedTopologies []struct {
+		MatchLabelExpressions []struct {
+			Key    string   `json:"key"`
+			Values []string `json:"values"`
+		} `json:"matchLabelExpressions"`
+	} `json:"allowedTopologies"`
+}
+
+func (sc rawStorageClass) isDefault() bool {
+	return sc.Metadata.Annotations["storageclass.kubernetes.io/is-default-class"] == "true" ||
+		sc.Metadata.Annotations["storageclass.beta.kubernetes.io/is-default-class"] == "true"
+}
+
+// zones returns the zones the class may provision in; nil means any
+func (sc rawStorageClass) zones() []string {
+	var zones []string
+	for _, term := range sc.AllowedTopologies {
+		for _, expr := range term.MatchLabelExpressions {
+			if isZoneKey(expr.Key) {
+				zones = append(zones, expr.Values...)
+			}
+		}
+	}
+	return zones
+}
+
+func isZoneKey(key string) bool {
+	return key == "topology.kubernetes.io/zone" || key == "failure-domain.beta.kubernetes.io/zone" || strings.HasSuffix(key, "/zone")
+}
+
+// DiagnosePVCs explains why claims are not bound: the named one, or every
+// Pending claim in the namespace. It looks for a missing or absent default
+// StorageClass, an uninstalled provisioner, zones the class cannot provision
+// in, quota and provisioning errors in the claim's events, and claims
+// waiting for a pod, and collects the steps that would fix them.
+func (s *SubAgent) DiagnosePVCs(ctx context.Context, name, namespace string) ([]*PVCDiagnosis, error) {
+	var claims []rawPVC
+	if name != "" {
+		data, err := s.client.GetJSON(ctx, "pvc", name, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get PVC %s: %w", name, err)
+		}
+		var pvc rawPVC
+		if err := json.Unmarshal(data, &pvc); err != nil {
+			return nil, fmt.Errorf("failed to parse PVC JSON: %w", err)
+		}
+		claims = append(claims, pvc)
+	} else {
+		out, err := s.client.RunWithNamespace(ctx, namespace, "get", "pvc", "-o", "json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list PVCs: %w", err)
+		}
+		var list struct {
+			Items []rawPVC `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(out), &list); err != nil {
+			return nil, fmt.Errorf("failed to parse PVC list JSON: %w", err)
+		}
+		for _, pvc := range list.Items {
+			if pvc.Status.Phase == "Pending" {
+				claims = append(claims, pvc)
+			}
+		}
+	}
+
+	var classes []rawStorageClass
+	if out, err := s.client.Run(ctx, "get", "storageclass", "-o", "json"); err == nil {
+		var list struct {
+			Items []rawStorageClass `json:"items"`
+		}
+		if json.Unmarshal([]byte(out), &list) == nil {
+			classes = list.Items
+		}
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].Metadata.Name < classes[j].Metadata.Name })
+
+	var diagnoses []*PVCDiagnosis
+	for _, pvc := range claims {
+		diagnoses = append(diagnoses, s.diagnosePVC(ctx, pvc, classes))
+	}
+	return diagnoses, nil
+}
+
+func (s *SubAgent) diagnosePVC(ctx context.Context, pvc rawPVC, classes []rawStorageClass) *PVCDiagnosis {
+	d := &PVCDiagnosis{PVC: pvc.Metadata.Name, Namespace: pvc.Metadata.Namespace, Status: pvc.Status.Phase}
+	if d.Status == "Bound" {
+		d.Notes = append(d.Notes, fmt.Sprintf("The claim is bound to volume %s; nothing to fix", pvc.Spec.VolumeName))
+		return d
+	}
+
+	var defaultClass *rawStorageClass
+	for i := range classes {
+		if classes[i].isDefault() {
+			defaultClass = &classes[i]
+			break
+		}
+	}
+
+	var sc *rawStorageClass
+	switch {
+	case pvc.Spec.StorageClassName == nil:
+		if defaultClass == nil {
+			s.noDefaultClass(d, classes)
+		} else {
+			sc = defaultClass
+		}
+	case *pvc.Spec.StorageClassName == "":
+		d.addCause("pre-provisioned volume requested",
+			`The claim sets storageClassName: "" so it only binds an existing PersistentVolume without a class; check kubectl get pv for an Available one with enough capacity and the same access modes`)
+	default:
+		for i := range classes {
+			if classes[i].Metadata.Name == *pvc.Spec.StorageClassName {
+				sc = &classes[i]
+			}
+		}
+		if sc == nil {
+			d.StorageClass = *pvc.Spec.StorageClassName
+			s.missingClass(d, pvc, defaultClass, classes)
+		}
+	}
+	if sc != nil {
+		d.StorageClass = sc.Metadata.Name
+		s.checkClass(ctx, d, pvc, *sc)
+	}
+	s.checkPVCEvents(ctx, d)
+	if len(d.Causes) == 0 {
+		d.Notes = append(d.Notes, fmt.Sprintf("No cause found; see kubectl describe pvc %s -n %s", d.PVC, d.Namespace))
+	}
+	return d
+}
+
+// noDefaultClass explains a claim without a class in a cluster without a
+// default one, and offers to make an existing class the default
+func (s *SubAgent) noDefaultClass(d *PVCDiagnosis, classes []rawStorageClass) {
+	if len(classes) == 0 {
+		d.addCause("no StorageClass", "The cluster has no StorageClass; install a CSI driver with one (for example the EBS CSI add-on on EKS) so claims can be provisioned")
+		return
+	}
+	candidate := classes[0].Metadata.Name
+	d.addCause("no default StorageClass", fmt.Sprintf("The claim names no StorageClass and none of the cluster's classes is the default; marking %s as default lets it provision", candidate))
+	d.Fixes = append(d.Fixes, StorageStep{
+		ID:          "set-default-storageclass",
+		Description: fmt.Sprintf("Make StorageClass %s the cluster default", candidate),
+		Command:     "kubectl",
+		Args:        []string{"patch", "storageclass", candidate, "-p", `{"metadata":{"annotations":{"storageclass.kubernetes.io/is-default-class":"true"}}}`},
+		Reason:      "Claims without a StorageClass use the default one",
+	})
+	d.Notes = append(d.Notes, "Kubernetes 1.28 and later give pending claims without a class the new default; older clusters need the claim recreated")
+}
+
+// missingClass explains a claim naming a StorageClass that does not exist.
+// storageClassName is immutable, so the fix recreates the claim, which
+// holds no data while it is pending, with the default class.
+func (s *SubAgent) missingClass(d *PVCDiagnosis, pvc rawPVC, defaultClass *rawStorageClass, classes []rawStorageClass) {
+	var names []string
+	for _, c := range classes {
+		names = append(names, c.Metadata.Name)
+	}
+	detail := fmt.Sprintf("StorageClass %s does not exist", d.StorageClass)
+	if len(names) > 0 {
+		detail += fmt.Sprintf(" (the cluster has %s)", strings.Join(names, ", "))
+	}
+	d.addCause("StorageClass not found", detail)
+	if defaultClass == nil {
+		return
+	}
+	d.Fixes = append(d.Fixes,
+		StorageStep{
+			ID:          "delete-pending-pvc",
+			Description: fmt.Sprintf("Delete pending PersistentVolumeClaim %s", d.PVC),
+			Command:     "kubectl",
+			Args:        []string{"delete", "pvc", d.PVC, "-n", d.Namespace},
+			Reason:      "storageClassName cannot be changed on an existing claim",
+		},
+		StorageStep{
+			ID:          "recreate-pvc",
+			Description: fmt.Sprintf("Recreate PersistentVolumeClaim %s with StorageClass %s", d.PVC, defaultClass.Metadata.Name),
+			Command:     "kubectl",
+			Args:        []string{"apply", "-f", "-"},
+			Manifest: s.pvc.generatePVCManifest(CreatePVCOptions{
+				Name:             d.PVC,
+				Namespace:        d.Namespace,
+				StorageClassName: defaultClass.Metadata.Name,
+				AccessModes:      pvc.Spec.AccessModes,
+				Storage:          pvc.Spec.Resources.Requests.Storage,
+				VolumeMode:       pvc.Spec.VolumeMode,
+				Labels:           pvc.Metadata.Labels,
+			}),
+			Reason: "Provision the claim with the cluster's default StorageClass",
+		},
+	)
+}
+
+// checkClass looks for reasons the claim's StorageClass cannot provision it
+func (s *SubAgent) checkClass(ctx context.Context, d *PVCDiagnosis, pvc rawPVC, sc rawStorageClass) {
+	switch {
+	case sc.Provisioner == "kubernetes.io/no-provisioner":
+		d.addCause("no dynamic provisioning", fmt.Sprintf("StorageClass %s has no provisioner, so the claim waits for a matching PersistentVolume to be created by hand", sc.Metadata.Name))
+	case strings.HasPrefix(sc.Provisioner, "kubernetes.io/"):
+		// In-tree provisioners have no CSIDriver object
+	default:
+		if out, err := s.client.Run(ctx, "get", "csidrivers", "-o", "name"); err == nil && !strings.Contains(out, "/"+sc.Provisioner+"\n") && !strings.HasSuffix(out, "/"+sc.Provisioner) {
+			d.addCause("provisioner not installed", fmt.Sprintf("StorageClass %s uses %s, but the cluster has no CSIDriver of that name; install its CSI driver", sc.Metadata.Name, sc.Provisioner))
+		}
+	}
+
+	if allowed := sc.zones(); len(allowed) > 0 {
+		if zones := s.nodeZones(ctx); len(zones) > 0 {
+			var usable []string
+			for _, z := range zones {
+				if containsString(allowed, z) {
+					usable = append(usable, z)
+				}
+			}
+			if len(usable) == 0 {
+				d.addCause("zone mismatch", fmt.Sprintf("StorageClass %s only provisions in %s, but the nodes run in %s", sc.Metadata.Name, strings.Join(allowed, ", "), strings.Join(zones, ", ")))
+			}
+		}
+	}
+
+	if sc.VolumeBindingMode == "WaitForFirstConsumer" && pvc.Metadata.Annotations["volume.kubernetes.io/selected-node"] == "" {
+		if !s.claimInUse(ctx, pvc) {
+			d.addCause("waiting for first consumer", fmt.Sprintf("StorageClass %s binds volumes only once a pod using the claim is scheduled, and no pod in %s uses it yet; this is expected until one does", sc.Metadata.Name, d.Namespace))
+		}
+	}
+}
+
+// checkPVCEvents reads the claim's warning events for quota, zone and
+// provisioning failures
+func (s *SubAgent) checkPVCEvents(ctx context.Context, d *PVCDiagnosis) {
+	out, err := s.client.RunWithNamespace(ctx, d.Namespace, "get", "events",
+		"--field-selector", "involvedObject.kind=PersistentVolumeClaim,involvedObject.name="+d.PVC, "-o", "json")
+	if err != nil {
+		return
+	}
+	var events struct {
+		Items []struct {
+			Type    string `json:"type"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"items"`
+	}
+	if json.Unmarshal([]byte(out), &events) != nil {
+		return
+	}
+	for _, e := range events.Items {
+		if e.Type != "Warning" {
+			continue
+		}
+		msg := strings.ToLower(e.Message)
+		switch {
+		case strings.Contains(msg, "quota"):
+			d.addCause("quota exceeded", e.Message)
+		case strings.Contains(msg, "zone") || strings.Contains(msg, "topology") || strings.Contains(msg, "node affinity"):
+			d.addCause("zone mismatch", e.Message)
+		case e.Reason == "ProvisioningFailed":
+			d.addCause("provisioning failed", e.Message)
+		}
+	}
+}
+
+// nodeZones returns the zones the cluster's nodes run in
+func (s *SubAgent) nodeZones(ctx context.Context) []string {
+	out, err := s.client.Run(ctx, "get", "nodes", "-o", "json")
+	if err != nil {
+		return nil
+	}
+	var nodes struct {
+		Items []struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if json.Unmarshal([]byte(out), &nodes) != nil {
+		return nil
+	}
+	var zones []string
+	for _, n := range nodes.Items {
+		zone := n.Metadata.Labels["topology.kubernetes.io/zone"]
+		if zone == "" {
+			zone = n.Metadata.Labels["failure-domain.beta.kubernetes.io/zone"]
+		}
+		if zone != "" && !containsString(zones, zone) {
+			zones = append(zones, zone)
+		}
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// claimInUse reports whether a pod in the claim's namespace mounts it
+func (s *SubAgent) claimInUse(ctx context.Context, pvc rawPVC) bool {
+	out, err := s.client.RunWithNamespace(ctx, pvc.Metadata.Namespace, "get", "pods", "-o", "json")
+	if err != nil {
+		return true // unknown; do not blame a missing consumer
+	}
+	var pods struct {
+		Items []struct {
+			Spec struct {
+				Volumes []struct {
+					PersistentVolumeClaim *struct {
+						ClaimName string `json:"claimName"`
+					} `json:"persistentVolumeClaim"`
+				} `json:"volumes"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if json.Unmarshal([]byte(out), &pods) != nil {
+		return true
+	}
+	for _, p := range pods.Items {
+		for _, v := range p.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == pvc.Metadata.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// quotaSteps returns steps raising the namespace's storage quotas that a
+// claim growing by delta would exceed
+func (s *SubAgent) quotaSteps(ctx context.Context, namespace, storageClass string, delta resource.Quantity) []StorageStep {
+	out, err := s.client.RunWithNamespace(ctx, namespace, "get", "resourcequota", "-o", "json")
+	if err != nil {
+		return nil
+	}
+	var quotas struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Hard map[string]string `json:"hard"`
+				Used map[string]string `json:"used"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if json.Unmarshal([]byte(out), &quotas) != nil {
+		return nil
+	}
+	keys := []string{"requests.storage", storageClass + ".storageclass.storage.k8s.io/requests.storage"}
+	var steps []StorageStep
+	for _, q := range quotas.Items {
+		for _, key := range keys {
+			hard, err := resource.ParseQuantity(q.Status.Hard[key])
+			if err != nil {
+				continue
+			}
+			need, _ := resource.ParseQuantity(q.Status.Used[key])
+			need.Add(delta)
+			if need.Cmp(hard) <= 0 {
+				continue
+			}
+			patch := fmt.Sprintf(`{"spec":{"hard":{%q:%q}}}`, key, need.String())
+			steps = append(steps, StorageStep{
+				ID:          "raise-quota-" + q.Metadata.Name,
+				Description: fmt.Sprintf("Raise %s in ResourceQuota %s from %s to %s", key, q.Metadata.Name, q.Status.Hard[key], need.String()),
+				Command:     "kubectl",
+				Args:        []string{"patch", "resourcequota", q.Metadata.Name, "-n", namespace, "--type=merge", "-p", patch},
+				Reason:      fmt.Sprintf("ResourceQuota %s would reject the larger request", q.Metadata.Name),
+			})
+		}
+	}
+	return steps
+}
+
+// PVCFixPlan turns the fixes found by DiagnosePVCs into a plan, or nil when
+// there is nothing to fix
+func PVCFixPlan(diagnoses []*PVCDiagnosis) *StoragePlan {
+	plan := &StoragePlan{Version: 1, CreatedAt: time.Now()}
+	var names []string
+	for _, d := range diagnoses {
+		if len(d.Fixes) == 0 {
+			continue
+		}
+		names = append(names, d.PVC)
+		plan.Steps = append(plan.Steps, d.Fixes...)
+		for _, line := range strings.Split(strings.TrimSpace(d.String()), "\n") {
+			plan.Notes = append(plan.Notes, strings.TrimSpace(line))
+		}
+	}
+	if len(plan.Steps) == 0 {
+		return nil
+	}
+	plan.Summary = fmt.Sprintf("Fix pending PersistentVolumeClaim %s", strings.Join(names, ", "))
+	return plan
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var expandPattern = regexp.MustCompile(`\b(?:expand|resize|grow|increase|enlarge)\s+(?:the\s+)?(?:pvc|persistentvolumeclaim|persistent\s+volume\s+claim|volume\s+claim|volume)\s+([a-z0-9][a-z0-9.-]*[a-z0-9])\s+to\s+(\d+(?:\.\d+)?)\s*([kmgtpe]i?)b?\b`)
+
+// ParseExpandQuery reads questions such as "expand pvc data to 50Gi" and
+// returns the claim and its new size as a Kubernetes quantity
+func ParseExpandQuery(query string) (name, size string, ok bool) {
+	m := expandPattern.FindStringSubmatch(strings.ToLower(query))
+	if m == nil {
+		return "", "", false
+	}
+	// Binary units are Ki, Mi, Gi...; decimal kilo is the only lower-case one
+	unit := strings.ToUpper(m[3][:1]) + m[3][1:]
+	if unit == "K" {
+		unit = "k"
+	}
+	return m[1], m[2] + unit, true
+}
+
+// resizeWaitTimeout bounds the plan's wait for the new capacity
+const resizeWaitTimeout = "10m"
+
+// ExpandPVCPlan checks that a bound claim can grow to size and returns a
+// plan that patches its request and waits for the new capacity. A
+// StorageClass without allowVolumeExpansion gets a step enabling it first.
+func (s *SubAgent) ExpandPVCPlan(ctx context.Context, name, namespace, size string) (*StoragePlan, error) {
+	pvc, err := s.pvc.GetPVC(ctx, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if pvc.Status != "Bound" {
+		return nil, fmt.Errorf("PVC %s is %s; only a bound claim can be expanded (ask why it is pending)", name, pvc.Status)
+	}
+	want, err := resource.ParseQuantity(size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid size %q: %w", size, err)
+	}
+	current := pvc.Capacity
+	if current == "" {
+		current = pvc.RequestedStorage
+	}
+	have, err := resource.ParseQuantity(current)
+	if err == nil && want.Cmp(have) <= 0 {
+		return nil, fmt.Errorf("PVC %s already has %s; volumes can only grow", name, current)
+	}
+	if pvc.StorageClassName == "" {
+		return nil, fmt.Errorf("PVC %s has no StorageClass; a statically provisioned volume cannot be expanded through its claim", name)
+	}
+	sc, err := s.pv.GetStorageClass(ctx, pvc.StorageClassName)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := s.pvc.ResizePVCPlan(name, namespace, size)
+	plan.Notes = []string{
+		fmt.Sprintf("PVC %s grows from %s to %s", name, current, size),
+		fmt.Sprintf("If the claim reports FileSystemResizePending, restart the pod using it to finish the resize: kubectl describe pvc %s -n %s", name, namespace),
+		"Volume shrinking is not supported",
+	}
+	if !sc.AllowVolumeExpansion {
+		plan.Steps = append([]StorageStep{{
+			ID:          "allow-volume-expansion",
+			Description: fmt.Sprintf("Allow volume expansion on StorageClass %s", sc.Name),
+			Command:     "kubectl",
+			Args:        []string{"patch", "storageclass", sc.Name, "-p", `{"allowVolumeExpansion":true}`},
+			Reason:      fmt.Sprintf("StorageClass %s does not allow expansion, so the API server rejects the resize", sc.Name),
+		}}, plan.Steps...)
+		plan.Notes = append(plan.Notes, fmt.Sprintf("Enabling expansion only works if the %s provisioner supports it", sc.Provisioner))
+	}
+	// Quota counts requests, so the growth must fit under it
+	requested, err := resource.ParseQuantity(pvc.RequestedStorage)
+	if err == nil && want.Cmp(requested) > 0 {
+		delta := want.DeepCopy()
+		delta.Sub(requested)
+		plan.Steps = append(s.quotaSteps(ctx, namespace, sc.Name, delta), plan.Steps...)
+	}
+	plan.Steps = append(plan.Steps, StorageStep{
+		ID:          "wait-for-resize",
+		Description: fmt.Sprintf("Wait for PersistentVolumeClaim %s to report %s", name, size),
+		Command:     "kubectl",
+		Args: []string{"wait", "pvc/" + name, "-n", namespace,
+			"--for=jsonpath={.status.capacity.storage}=" + size, "--timeout=" + resizeWaitTimeout},
+		Reason: "The controller and the node resize the volume after the patch; the claim's capacity changes when they finish",
+	})
+	return plan, nil
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// routedClient answers kubectl calls from fixed output keyed by namespace
+// and arguments, and GetJSON from objects keyed by kind/name/namespace
+type routedClient struct {
+	mockClient
+	run     map[string]string // "ns: args"; ": args" without a namespace
+	objects map[string]string
+}
+
+func (c *routedClient) Run(ctx context.Context, args ...string) (string, error) {
+	return c.RunWithNamespace(ctx, "", args...)
+}
+
+func (c *routedClient) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	if out, ok := c.run[namespace+": "+strings.Join(args, " ")]; ok {
+		return out, nil
+	}
+	return "", errors.New("not found")
+}
+
+func (c *routedClient) GetJSON(ctx context.Context, resourceType, name, namespace string) ([]byte, error) {
+	if out, ok := c.objects[resourceType+"/"+name+"/"+namespace]; ok {
+		return []byte(out), nil
+	}
+	return nil, errors.New("not found")
+}
+
+const (
+	boundPVC   = `{"metadata": {"name": "data", "namespace": "db"}, "spec": {"storageClassName": "gp3", "accessModes": ["ReadWriteOnce"], "resources": {"requests": {"storage": "20Gi"}}}, "status": {"phase": "Bound", "capacity": {"storage": "20Gi"}}}`
+	gp3Class   = `{"metadata": {"name": "gp3"}, "provisioner": "ebs.csi.aws.com", "allowVolumeExpansion": %s}`
+	dbQuota    = `{"items": [{"metadata": {"name": "storage"}, "status": {"hard": {"requests.storage": "50Gi"}, "used": {"requests.storage": "40Gi"}}}]}`
+	gp3List    = `{"items": [{"metadata": {"name": "gp3", "annotations": {"storageclass.kubernetes.io/is-default-class": "true"}}, "provisioner": "ebs.csi.aws.com", "volumeBindingMode": "WaitForFirstConsumer"}]}`
+	csiDrivers = "csidriver.storage.k8s.io/ebs.csi.aws.com\n"
+)
+
+func TestParseExpandQuery(t *testing.T) {
+	tests := []struct {
+		query, name, size string
+		ok                bool
+	}{
+		{"expand PVC data to 50Gi", "data", "50Gi", true},
+		{"resize the volume claim pg-data-0 to 1.5ti", "pg-data-0", "1.5Ti", true},
+		{"grow pvc logs to 500G", "logs", "500G", true},
+		{"list pvcs", "", "", false},
+	}
+	for _, tt := range tests {
+		name, size, ok := ParseExpandQuery(tt.query)
+		if name != tt.name || size != tt.size || ok != tt.ok {
+			t.Errorf("ParseExpandQuery(%q) = %q, %q, %v", tt.query, name, size, ok)
+		}
+	}
+}
+
+func TestExpandPVCPlan(t *testing.T) {
+	client := &routedClient{
+		run: map[string]string{"db: get resourcequota -o json": dbQuota},
+		objects: map[string]string{
+			"pvc/data/db":       boundPVC,
+			"storageclass/gp3/": strings.Replace(gp3Class, "%s", "false", 1),
+		},
+	}
+	plan, err := NewSubAgent(client, false).ExpandPVCPlan(context.Background(), "data", "db", "50Gi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, step := range plan.Steps {
+		ids = append(ids, step.ID)
+	}
+	if strings.Join(ids, ",") != "raise-quota-storage,allow-volume-expansion,resize-pvc,wait-for-resize" {
+		t.Fatalf("steps = %v", ids)
+	}
+	if got := strings.Join(plan.Steps[0].Args, " "); !strings.Contains(got, `{"spec":{"hard":{"requests.storage":"70Gi"}}}`) {
+		t.Errorf("quota patch = %s", got)
+	}
+	if got := strings.Join(plan.Steps[3].Args, " "); got != "wait pvc/data -n db --for=jsonpath={.status.capacity.storage}=50Gi --timeout=10m" {
+		t.Errorf("wait = %s", got)
+	}
+
+	// An expandable class under quota needs only the resize
+	client.objects["storageclass/gp3/"] = strings.Replace(gp3Class, "%s", "true", 1)
+	client.run["db: get resourcequota -o json"] = `{"items": []}`
+	plan, err = NewSubAgent(client, false).ExpandPVCPlan(context.Background(), "data", "db", "50Gi")
+	if err != nil || len(plan.Steps) != 2 {
+		t.Errorf("plan = %+v, %v", plan, err)
+	}
+
+	if _, err := NewSubAgent(client, false).ExpandPVCPlan(context.Background(), "data", "db", "10Gi"); err == nil || !strings.Contains(err.Error(), "only grow") {
+		t.Errorf("shrink err = %v", err)
+	}
+}
+
+func TestParsePendingQuery(t *testing.T) {
+	if name, ok := ParsePendingQuery("why is my PVC pending?"); !ok || name != "" {
+		t.Errorf("got %q, %v", name, ok)
+	}
+	if name, ok := ParsePendingQuery("pvc pg-data is stuck"); !ok || name != "pg-data" {
+		t.Errorf("got %q, %v", name, ok)
+	}
+	if _, ok := ParsePendingQuery("list pending pods"); ok {
+		t.Error("pending pods matched")
+	}
+}
+
+func TestDiagnosePendingPVCs(t *testing.T) {
+	pending := func(class string) string {
+		return `{"metadata": {"name": "data", "namespace": "db"}, "spec": {` + class + `"accessModes": ["ReadWriteOnce"], "resources": {"requests": {"storage": "20Gi"}}}, "status": {"phase": "Pending"}}`
+	}
+	tests := []struct {
+		name    string
+		pvc     string
+		classes string
+		extra   map[string]string
+		cause   string
+		fixes   []string
+	}{
+		{
+			name: "no default class", pvc: pending(""),
+			classes: `{"items": [{"metadata": {"name": "standard"}, "provisioner": "ebs.csi.aws.com"}]}`,
+			cause:   "no default StorageClass", fixes: []string{"set-default-storageclass"},
+		},
+		{
+			name: "missing class", pvc: pending(`"storageClassName": "fast",`), classes: gp3List,
+			cause: "StorageClass not found", fixes: []string{"delete-pending-pvc", "recreate-pvc"},
+		},
+		{
+			name: "provisioner missing", pvc: pending(`"storageClassName": "gp3",`), classes: gp3List,
+			extra: map[string]string{": get csidrivers -o name": "csidriver.storage.k8s.io/efs.csi.aws.com\n", "db: get pods -o json": `{"items": [{"spec": {"volumes": [{"persistentVolumeClaim": {"claimName": "data"}}]}}]}`},
+			cause: "provisioner not installed",
+		},
+		{
+			name: "waiting for consumer", pvc: pending(""), classes: gp3List,
+			extra: map[string]string{": get csidrivers -o name": csiDrivers, "db: get pods -o json": `{"items": []}`},
+			cause: "waiting for first consumer",
+		},
+		{
+			name: "zone mismatch", pvc: pending(`"storageClassName": "zonal",`),
+			classes: `{"items": [{"metadata": {"name": "zonal"}, "provisioner": "kubernetes.io/aws-ebs",
+				"allowedTopologies": [{"matchLabelExpressions": [{"key": "topology.kubernetes.io/zone", "values": ["us-east-1a"]}]}]}]}`,
+			extra: map[string]string{": get nodes -o json": `{"items": [{"metadata": {"labels": {"topology.kubernetes.io/zone": "us-east-1b"}}}]}`},
+			cause: "zone mismatch",
+		},
+		{
+			name: "cloud quota", pvc: pending(`"storageClassName": "gp3",`), classes: gp3List,
+			extra: map[string]string{
+				": get csidrivers -o name": csiDrivers,
+				"db: get pods -o json":     `{"items": [{"spec": {"volumes": [{"persistentVolumeClaim": {"claimName": "data"}}]}}]}`,
+				"db: get events --field-selector involvedObject.kind=PersistentVolumeClaim,involvedObject.name=data -o json": `{"items": [{"type": "Warning", "reason": "ProvisioningFailed", "message": "VolumeLimitExceeded: quota for gp3 volumes exceeded"}]}`,
+			},
+			cause: "quota exceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &routedClient{
+				run:     map[string]string{": get storageclass -o json": tt.classes},
+				objects: map[string]string{"pvc/data/db": tt.pvc},
+			}
+			for k, v := range tt.extra {
+				client.run[k] = v
+			}
+			diagnoses, err := NewSubAgent(client, false).DiagnosePVCs(context.Background(), "data", "db")
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := diagnoses[0]
+			if len(d.Causes) == 0 || d.Causes[0].Reason != tt.cause {
+				t.Fatalf("causes = %+v, want %s", d.Causes, tt.cause)
+			}
+			var fixes []string
+			for _, f := range d.Fixes {
+				fixes = append(fixes, f.ID)
+			}
+			if strings.Join(fixes, ",") != strings.Join(tt.fixes, ",") {
+				t.Errorf("fixes = %v, want %v", fixes, tt.fixes)
+			}
+			if plan := PVCFixPlan(diagnoses); (plan != nil) != (len(tt.fixes) > 0) {
+				t.Errorf("fix plan = %+v", plan)
+			}
+		})
+	}
+}
+
+func TestHandleQueryPendingPVCPlansFixes(t *testing.T) {
+	client := &routedClient{
+		run: map[string]string{
+			"db: get pvc -o json":        `{"items": [{"metadata": {"name": "data", "namespace": "db"}, "spec": {"storageClassName": "fast"}, "status": {"phase": "Pending"}}, {"metadata": {"name": "logs", "namespace": "db"}, "status": {"phase": "Bound"}}]}`,
+			": get storageclass -o json": gp3List,
+		},
+	}
+	resp, err := NewSubAgent(client, false).HandleQuery(context.Background(), "why is my pvc pending in namespace db", QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Type != ResponseTypePlan || resp.Plan.Summary != "Fix pending PersistentVolumeClaim data" {
+		t.Fatalf("response = %+v", resp)
+	}
+	if !strings.Contains(resp.Plan.Steps[1].Manifest, "storageClassName: gp3") {
+		t.Errorf("recreated claim:\n%s", resp.Plan.Steps[1].Manifest)
+	}
+}
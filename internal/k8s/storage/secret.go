@@ -288,9 +288,7 @@ func (m *SecretManager) parseSecretList(data []byte) ([]SecretInfo, error) {
 	for _, item := range list.Items {
 		secret, err := m.parseSecret(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[storage] failed to parse Secret: %v\n", err)
-			}
+			logger.Debugf("failed to parse Secret: %v", err)
 			continue
 		}
 		secrets = append(secrets, *secret)
@@ -0,0 +1,545 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	snapshotAPIGroup   = "snapshot.storage.k8s.io"
+	snapshotAPIVersion = snapshotAPIGroup + "/v1"
+
+	// defaultSnapshotClassAnnotation marks the class snapshots of a driver
+	// use when they name none
+	defaultSnapshotClassAnnotation = "snapshot.storage.kubernetes.io/is-default-class"
+
+	snapshotWaitTimeout = "10m"
+)
+
+// snapshotNow stamps snapshot names; replaced in tests
+var snapshotNow = time.Now
+
+var (
+	snapshotClaimAfterPattern  = regexp.MustCompile(`\b(?:pvc|persistentvolumeclaim|claim|volume)s?[\s/]+(?:named?\s+)?([a-z0-9][a-z0-9.-]*[a-z0-9])`)
+	snapshotClaimBeforePattern = regexp.MustCompile(`\b([a-z0-9][a-z0-9.-]*[a-z0-9])\s+(?:pvc|persistentvolumeclaim|claim|volume)s?\b`)
+	restoreSnapshotPattern     = regexp.MustCompile(`\b(?:volume\s*)?snapshot\s+(?:named?\s+)?([a-z0-9][a-z0-9.-]*[a-z0-9])`)
+	restoreTargetPattern       = regexp.MustCompile(`\b(?:into|to|as)\s+(?:(?:a|the)\s+)?(?:new\s+)?(?:pvc|persistentvolumeclaim|claim|volume)\s+([a-z0-9][a-z0-9.-]*[a-z0-9])`)
+	snapshotReadWords          = []string{"list ", "show ", "get ", "describe ", "what ", "which "}
+	notSnapshotNames           = map[string]bool{"the": true, "a": true, "an": true, "my": true, "our": true, "this": true,
+		"that": true, "of": true, "for": true, "before": true, "after": true, "first": true, "in": true, "into": true,
+		"to": true, "as": true, "from": true, "and": true, "then": true, "snapshot": true, "snapshots": true,
+		"volume": true, "volumes": true, "named": true, "new": true, "pvc": true, "claim": true, "persistent": true,
+		"upgrade": true, "upgrading": true, "take": true, "create": true, "make": true, "restore": true, "its": true}
+)
+
+// ParseSnapshotQuery reads requests such as "snapshot the postgres PVC
+// before upgrade" or "take a volume snapshot of pvc data" and returns the
+// claim, or the workload whose claims to snapshot
+func ParseSnapshotQuery(query string) (claim string, ok bool) {
+	lower := strings.ToLower(query)
+	if !strings.Contains(lower, "snapshot") || strings.Contains(lower, "restore") ||
+		containsAny(lower, snapshotReadWords) || strings.HasPrefix(lower, "list") || strings.HasPrefix(lower, "show") {
+		return "", false
+	}
+	for _, pattern := range []*regexp.Regexp{snapshotClaimAfterPattern, snapshotClaimBeforePattern} {
+		for _, m := range pattern.FindAllStringSubmatch(lower, -1) {
+			if !notSnapshotNames[m[1]] {
+				return m[1], true
+			}
+		}
+	}
+	return "", false
+}
+
+// ParseRestoreQuery reads requests such as "restore snapshot data-20240101-1200
+// into pvc data-restored". target is empty when the request names no claim.
+func ParseRestoreQuery(query string) (snapshot, target string, ok bool) {
+	lower := strings.ToLower(query)
+	if !strings.Contains(lower, "restore") {
+		return "", "", false
+	}
+	for _, m := range restoreSnapshotPattern.FindAllStringSubmatch(lower, -1) {
+		if !notSnapshotNames[m[1]] {
+			snapshot = m[1]
+			break
+		}
+	}
+	if snapshot == "" {
+		return "", "", false
+	}
+	if m := restoreTargetPattern.FindStringSubmatch(lower); m != nil {
+		target = m[1]
+	}
+	return snapshot, target, true
+}
+
+// VolumeSnapshotClassInfo contains VolumeSnapshotClass information
+type VolumeSnapshotClassInfo struct {
+	Name           string `json:"name"`
+	Driver         string `json:"driver"`
+	DeletionPolicy string `json:"deletionPolicy"`
+	IsDefault      bool   `json:"isDefault"`
+}
+
+// VolumeSnapshotInfo contains VolumeSnapshot information
+type VolumeSnapshotInfo struct {
+	Name         string    `json:"name"`
+	Namespace    string    `json:"namespace"`
+	PVC          string    `json:"pvc"`
+	Class        string    `json:"class,omitempty"`
+	ReadyToUse   bool      `json:"readyToUse"`
+	RestoreSize  string    `json:"restoreSize,omitempty"`
+	Age          string    `json:"age"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ErrorMessage string    `json:"error,omitempty"`
+}
+
+type rawSnapshotClass struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Driver         string `json:"driver"`
+	DeletionPolicy string `json:"deletionPolicy"`
+}
+
+type rawSnapshot struct {
+	Metadata struct {
+		Name              string `json:"name"`
+		Namespace         string `json:"namespace"`
+		CreationTimestamp string `json:"creationTimestamp"`
+	} `json:"metadata"`
+	Spec struct {
+		VolumeSnapshotClassName string `json:"volumeSnapshotClassName"`
+		Source                  struct {
+			PersistentVolumeClaimName string `json:"persistentVolumeClaimName"`
+		} `json:"source"`
+	} `json:"spec"`
+	Status struct {
+		ReadyToUse  bool   `json:"readyToUse"`
+		RestoreSize string `json:"restoreSize"`
+		Error       *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"status"`
+}
+
+// rawPV keeps the fields that locate a volume's disk in its cloud
+type rawPV struct {
+	Metadata struct {
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Spec struct {
+		CSI *struct {
+			Driver       string `json:"driver"`
+			VolumeHandle string `json:"volumeHandle"`
+		} `json:"csi"`
+		AWSElasticBlockStore *struct {
+			VolumeID string `json:"volumeID"`
+		} `json:"awsElasticBlockStore"`
+		GCEPersistentDisk *struct {
+			PDName string `json:"pdName"`
+		} `json:"gcePersistentDisk"`
+		NodeAffinity *struct {
+			Required struct {
+				NodeSelectorTerms []struct {
+					MatchExpressions []struct {
+						Key    string   `json:"key"`
+						Values []string `json:"values"`
+					} `json:"matchExpressions"`
+				} `json:"nodeSelectorTerms"`
+			} `json:"required"`
+		} `json:"nodeAffinity"`
+	} `json:"spec"`
+}
+
+// zone returns the zone the volume's disk lives in
+func (pv rawPV) zone() string {
+	for _, key := range []string{"topology.kubernetes.io/zone", "failure-domain.beta.kubernetes.io/zone"} {
+		if z := pv.Metadata.Labels[key]; z != "" {
+			return z
+		}
+	}
+	if pv.Spec.NodeAffinity != nil {
+		for _, term := range pv.Spec.NodeAffinity.Required.NodeSelectorTerms {
+			for _, expr := range term.MatchExpressions {
+				if isZoneKey(expr.Key) && len(expr.Values) == 1 {
+					return expr.Values[0]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// ListVolumeSnapshotClasses lists the cluster's VolumeSnapshotClasses. It
+// fails when the snapshot CRDs are not installed.
+func (s *SubAgent) ListVolumeSnapshotClasses(ctx context.Context) ([]VolumeSnapshotClassInfo, error) {
+	out, err := s.client.Run(ctx, "get", "volumesnapshotclass", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VolumeSnapshotClasses: %w", err)
+	}
+	var list struct {
+		Items []rawSnapshotClass `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse VolumeSnapshotClass list: %w", err)
+	}
+	classes := make([]VolumeSnapshotClassInfo, 0, len(list.Items))
+	for _, c := range list.Items {
+		classes = append(classes, VolumeSnapshotClassInfo{
+			Name:           c.Metadata.Name,
+			Driver:         c.Driver,
+			DeletionPolicy: c.DeletionPolicy,
+			IsDefault:      c.Metadata.Annotations[defaultSnapshotClassAnnotation] == "true",
+		})
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].Name < classes[j].Name })
+	return classes, nil
+}
+
+// ListVolumeSnapshots lists the VolumeSnapshots in a namespace
+func (s *SubAgent) ListVolumeSnapshots(ctx context.Context, namespace string) ([]VolumeSnapshotInfo, error) {
+	out, err := s.client.RunWithNamespace(ctx, namespace, "get", "volumesnapshot", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VolumeSnapshots: %w", err)
+	}
+	var list struct {
+		Items []rawSnapshot `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse VolumeSnapshot list: %w", err)
+	}
+	snapshots := make([]VolumeSnapshotInfo, 0, len(list.Items))
+	for _, raw := range list.Items {
+		createdAt, _ := time.Parse(time.RFC3339, raw.Metadata.CreationTimestamp)
+		info := VolumeSnapshotInfo{
+			Name:        raw.Metadata.Name,
+			Namespace:   raw.Metadata.Namespace,
+			PVC:         raw.Spec.Source.PersistentVolumeClaimName,
+			Class:       raw.Spec.VolumeSnapshotClassName,
+			ReadyToUse:  raw.Status.ReadyToUse,
+			RestoreSize: raw.Status.RestoreSize,
+			Age:         formatDuration(time.Since(createdAt)),
+			CreatedAt:   createdAt,
+		}
+		if raw.Status.Error != nil {
+			info.ErrorMessage = raw.Status.Error.Message
+		}
+		snapshots = append(snapshots, info)
+	}
+	return snapshots, nil
+}
+
+// SnapshotPlanForQuery builds the snapshot plan a request such as "upgrade
+// to 1.30 and snapshot the postgres pvc first" asks for. ok is false when
+// the request asks for no snapshot. A namespace named in the request wins
+// over namespace.
+func (s *SubAgent) SnapshotPlanForQuery(ctx context.Context, query, namespace string) (plan *StoragePlan, ok bool, err error) {
+	claim, ok := ParseSnapshotQuery(query)
+	if !ok {
+		return nil, false, nil
+	}
+	if ns := s.extractNamespace(strings.ToLower(query)); ns != "" {
+		namespace = ns
+	}
+	plan, err = s.SnapshotPVCPlan(ctx, claim, namespace)
+	return plan, true, err
+}
+
+// SnapshotPVCPlan returns a plan taking a VolumeSnapshot of each claim name
+// resolves to, adding a VolumeSnapshotClass for the volume's CSI driver when
+// the cluster has none. Without the snapshot CRDs, or for in-tree volumes,
+// EBS and Persistent Disk volumes are snapshotted through their cloud CLI.
+func (s *SubAgent) SnapshotPVCPlan(ctx context.Context, name, namespace string) (*StoragePlan, error) {
+	claims, err := s.snapshotClaims(ctx, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	classes, classErr := s.ListVolumeSnapshotClasses(ctx)
+
+	plan := &StoragePlan{Version: 1, CreatedAt: time.Now()}
+	stamp := snapshotNow().UTC().Format("20060102-1504")
+	addedClasses := map[string]bool{}
+	var names, waits, cloud []string
+	for _, pvc := range claims {
+		if pvc.Status != "Bound" {
+			return nil, fmt.Errorf("PVC %s is %s; only a bound claim has data to snapshot", pvc.Name, pvc.Status)
+		}
+		names = append(names, pvc.Name)
+		snapshot := pvc.Name + "-" + stamp
+		pv := s.claimVolume(ctx, pvc)
+
+		driver := ""
+		if pv.Spec.CSI != nil {
+			driver = pv.Spec.CSI.Driver
+		}
+		if classErr == nil && driver != "" {
+			class := chooseSnapshotClass(classes, driver)
+			if class == "" {
+				class = snapshotClassName(driver)
+				if !addedClasses[class] {
+					addedClasses[class] = true
+					plan.Steps = append(plan.Steps, StorageStep{
+						ID:          "create-snapshotclass-" + class,
+						Description: fmt.Sprintf("Create VolumeSnapshotClass %s for %s", class, driver),
+						Command:     "kubectl",
+						Args:        []string{"apply", "-f", "-"},
+						Manifest:    snapshotClassManifest(class, driver),
+						Reason:      fmt.Sprintf("The cluster has no VolumeSnapshotClass for %s", driver),
+					})
+					plan.Notes = append(plan.Notes, fmt.Sprintf("VolumeSnapshotClass %s retains its snapshots when the VolumeSnapshot objects are deleted", class))
+				}
+			}
+			plan.Steps = append(plan.Steps, StorageStep{
+				ID:          "snapshot-" + pvc.Name,
+				Description: fmt.Sprintf("Snapshot PersistentVolumeClaim %s as %s", pvc.Name, snapshot),
+				Command:     "kubectl",
+				Args:        []string{"apply", "-f", "-"},
+				Manifest:    volumeSnapshotManifest(snapshot, namespace, class, pvc.Name),
+				Reason:      fmt.Sprintf("VolumeSnapshot through %s with class %s", driver, class),
+			})
+			waits = append(waits, "volumesnapshot/"+snapshot)
+			continue
+		}
+
+		step, err := cloudSnapshotStep(pv, pvc, snapshot)
+		if err != nil {
+			if classErr != nil {
+				return nil, fmt.Errorf("the cluster has no VolumeSnapshot support (%v), and %w", classErr, err)
+			}
+			return nil, err
+		}
+		plan.Steps = append(plan.Steps, step)
+		cloud = append(cloud, step.Command)
+	}
+
+	plan.Summary = fmt.Sprintf("Snapshot PersistentVolumeClaim %s in %s", strings.Join(names, ", "), namespace)
+	if len(waits) > 0 {
+		plan.Notes = append(plan.Notes,
+			fmt.Sprintf("Snapshots are usable once ready: kubectl wait %s -n %s --for=jsonpath={.status.readyToUse}=true --timeout=%s",
+				strings.Join(waits, " "), namespace, snapshotWaitTimeout),
+			"Restore one into a new claim by asking to restore snapshot <name> into pvc <new-name>")
+	}
+	if len(cloud) > 0 {
+		if classErr != nil {
+			plan.Notes = append(plan.Notes, "The cluster has no VolumeSnapshot CRDs, so the disks are snapshotted through the cloud provider")
+		}
+		plan.Notes = append(plan.Notes,
+			"Cloud snapshots are crash-consistent; stop writes or flush the database first for an application-consistent copy",
+			"Restore a cloud snapshot by creating a disk from it (aws ec2 create-volume --snapshot-id, gcloud compute disks create --source-snapshot) and a PersistentVolume for that disk")
+	}
+	return plan, nil
+}
+
+// snapshotClaims resolves name to claims: the claim itself, or the claims
+// of a workload such as the StatefulSet postgres, named data-postgres-0
+func (s *SubAgent) snapshotClaims(ctx context.Context, name, namespace string) ([]PVCInfo, error) {
+	if pvc, err := s.pvc.GetPVC(ctx, name, namespace); err == nil {
+		return []PVCInfo{*pvc}, nil
+	}
+	pvcs, err := s.pvc.ListPVCs(ctx, namespace, QueryOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var claims []PVCInfo
+	for _, pvc := range pvcs {
+		if strings.Contains("-"+pvc.Name+"-", "-"+name+"-") || pvc.Labels["app"] == name ||
+			pvc.Labels["app.kubernetes.io/name"] == name || pvc.Labels["app.kubernetes.io/instance"] == name {
+			claims = append(claims, pvc)
+		}
+	}
+	if len(claims) == 0 {
+		return nil, fmt.Errorf("no PVC named %s, or belonging to %s, in namespace %s", name, name, namespace)
+	}
+	return claims, nil
+}
+
+// claimVolume reads the PersistentVolume bound to a claim; a zero value
+// when it cannot be read
+func (s *SubAgent) claimVolume(ctx context.Context, pvc PVCInfo) rawPV {
+	var pv rawPV
+	if pvc.Volume == "" {
+		return pv
+	}
+	data, err := s.client.GetJSON(ctx, "pv", pvc.Volume, "")
+	if err != nil {
+		logger.Debugf("failed to get PV %s: %v", pvc.Volume, err)
+		return pv
+	}
+	if err := json.Unmarshal(data, &pv); err != nil {
+		logger.Debugf("failed to parse PV %s: %v", pvc.Volume, err)
+	}
+	return pv
+}
+
+// chooseSnapshotClass picks the driver's default VolumeSnapshotClass, or
+// its only one
+func chooseSnapshotClass(classes []VolumeSnapshotClassInfo, driver string) string {
+	var matching []string
+	for _, c := range classes {
+		if c.Driver != driver {
+			continue
+		}
+		if c.IsDefault {
+			return c.Name
+		}
+		matching = append(matching, c.Name)
+	}
+	if len(matching) > 0 {
+		return matching[0]
+	}
+	return ""
+}
+
+// snapshotClassName names the class created for a driver, such as
+// ebs-csi-aws-com-snapclass
+func snapshotClassName(driver string) string {
+	return strings.ReplaceAll(driver, ".", "-") + "-snapclass"
+}
+
+func snapshotClassManifest(name, driver string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "apiVersion: %s\n", snapshotAPIVersion)
+	sb.WriteString("kind: VolumeSnapshotClass\n")
+	sb.WriteString("metadata:\n")
+	fmt.Fprintf(&sb, "  name: %s\n", name)
+	fmt.Fprintf(&sb, "driver: %s\n", driver)
+	sb.WriteString("deletionPolicy: Retain\n")
+	return sb.String()
+}
+
+func volumeSnapshotManifest(name, namespace, class, pvc string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "apiVersion: %s\n", snapshotAPIVersion)
+	sb.WriteString("kind: VolumeSnapshot\n")
+	sb.WriteString("metadata:\n")
+	fmt.Fprintf(&sb, "  name: %s\n", name)
+	fmt.Fprintf(&sb, "  namespace: %s\n", namespace)
+	sb.WriteString("spec:\n")
+	fmt.Fprintf(&sb, "  volumeSnapshotClassName: %s\n", class)
+	sb.WriteString("  source:\n")
+	fmt.Fprintf(&sb, "    persistentVolumeClaimName: %s\n", pvc)
+	return sb.String()
+}
+
+// cloudSnapshotStep snapshots an EBS volume or a Persistent Disk through
+// the cloud CLI, for clusters that cannot take a VolumeSnapshot of it
+func cloudSnapshotStep(pv rawPV, pvc PVCInfo, snapshot string) (StorageStep, error) {
+	step := StorageStep{
+		ID:          "snapshot-" + pvc.Name,
+		Description: fmt.Sprintf("Snapshot the disk of PersistentVolumeClaim %s as %s", pvc.Name, snapshot),
+		Reason:      "No CSI VolumeSnapshot is available for this volume",
+	}
+	switch {
+	case pv.Spec.CSI != nil && pv.Spec.CSI.Driver == "ebs.csi.aws.com":
+		step.Command, step.Args = "aws", ebsSnapshotArgs(pv.Spec.CSI.VolumeHandle, pvc, snapshot)
+	case pv.Spec.AWSElasticBlockStore != nil:
+		// In-tree volume IDs look like aws://us-east-1a/vol-0abc
+		step.Command, step.Args = "aws", ebsSnapshotArgs(path.Base(pv.Spec.AWSElasticBlockStore.VolumeID), pvc, snapshot)
+	case pv.Spec.CSI != nil && pv.Spec.CSI.Driver == "pd.csi.storage.gke.io":
+		// projects/<project>/zones/<zone>/disks/<disk>, or regions/<region>
+		parts := strings.Split(pv.Spec.CSI.VolumeHandle, "/")
+		if len(parts) != 6 || parts[0] != "projects" || parts[4] != "disks" {
+			return step, fmt.Errorf("unrecognised Persistent Disk handle %q for PVC %s", pv.Spec.CSI.VolumeHandle, pvc.Name)
+		}
+		location := "--source-disk-zone"
+		if parts[2] == "regions" {
+			location = "--source-disk-region"
+		}
+		step.Command = "gcloud"
+		step.Args = []string{"compute", "snapshots", "create", snapshot, "--source-disk", parts[5], location, parts[3], "--project", parts[1]}
+	case pv.Spec.GCEPersistentDisk != nil:
+		zone := pv.zone()
+		if zone == "" {
+			return step, fmt.Errorf("cannot find the zone of Persistent Disk %s for PVC %s", pv.Spec.GCEPersistentDisk.PDName, pvc.Name)
+		}
+		step.Command = "gcloud"
+		step.Args = []string{"compute", "snapshots", "create", snapshot, "--source-disk", pv.Spec.GCEPersistentDisk.PDName, "--source-disk-zone", zone}
+	default:
+		driver := "an unknown volume source"
+		if pv.Spec.CSI != nil {
+			driver = pv.Spec.CSI.Driver
+		}
+		return step, fmt.Errorf("PVC %s uses %s, which has no cloud snapshot fallback (only EBS and Persistent Disk volumes do)", pvc.Name, driver)
+	}
+	return step, nil
+}
+
+func ebsSnapshotArgs(volumeID string, pvc PVCInfo, snapshot string) []string {
+	tags := fmt.Sprintf("ResourceType=snapshot,Tags=[{Key=Name,Value=%s},{Key=kubernetes.io/created-for/pvc/namespace,Value=%s},{Key=kubernetes.io/created-for/pvc/name,Value=%s}]",
+		snapshot, pvc.Namespace, pvc.Name)
+	return []string{"ec2", "create-snapshot", "--volume-id", volumeID, "--tag-specifications", tags}
+}
+
+// RestoreSnapshotPlan returns a plan creating a claim from a ready
+// VolumeSnapshot. A snapshot restores into a new claim, named target or
+// <source>-restored, with the source claim's class, access modes and size.
+func (s *SubAgent) RestoreSnapshotPlan(ctx context.Context, snapshot, target, namespace string) (*StoragePlan, error) {
+	data, err := s.client.GetJSON(ctx, "volumesnapshot", snapshot, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VolumeSnapshot %s: %w", snapshot, err)
+	}
+	var raw rawSnapshot
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse VolumeSnapshot JSON: %w", err)
+	}
+	if !raw.Status.ReadyToUse {
+		return nil, fmt.Errorf("VolumeSnapshot %s is not ready to use yet; wait with kubectl wait volumesnapshot/%s -n %s --for=jsonpath={.status.readyToUse}=true",
+			snapshot, snapshot, namespace)
+	}
+
+	source := raw.Spec.Source.PersistentVolumeClaimName
+	opts := CreatePVCOptions{
+		Namespace:          namespace,
+		AccessModes:        []string{string(AccessModeReadWriteOnce)},
+		Storage:            raw.Status.RestoreSize,
+		DataSourceSnapshot: snapshot,
+	}
+	if src, err := s.pvc.GetPVC(ctx, source, namespace); err == nil {
+		opts.StorageClassName = src.StorageClassName
+		opts.AccessModes = src.AccessModes
+		opts.VolumeMode = src.VolumeMode
+		if opts.Storage == "" {
+			opts.Storage = src.RequestedStorage
+		}
+	}
+	if opts.Storage == "" {
+		return nil, fmt.Errorf("VolumeSnapshot %s reports no restore size and its source PVC %s is gone; create the claim by hand with a size", snapshot, source)
+	}
+
+	if target == "" {
+		target = source + "-restored"
+		if source == "" {
+			target = snapshot + "-restored"
+		}
+	}
+	if _, err := s.pvc.GetPVC(ctx, target, namespace); err == nil {
+		return nil, fmt.Errorf("PVC %s already exists; a snapshot restores into a new claim, so ask to restore snapshot %s into pvc <new-name>", target, snapshot)
+	}
+	opts.Name = target
+
+	return &StoragePlan{
+		Version:   1,
+		CreatedAt: time.Now(),
+		Summary:   fmt.Sprintf("Restore VolumeSnapshot %s into PersistentVolumeClaim %s", snapshot, target),
+		Steps: []StorageStep{{
+			ID:          "restore-pvc",
+			Description: fmt.Sprintf("Create PersistentVolumeClaim %s from VolumeSnapshot %s", target, snapshot),
+			Command:     "kubectl",
+			Args:        []string{"apply", "-f", "-"},
+			Manifest:    s.pvc.generatePVCManifest(opts),
+			Reason:      "The CSI driver provisions a new volume holding the snapshot's data",
+		}},
+		Notes: []string{
+			fmt.Sprintf("Point the workload at PVC %s to use the restored data", target),
+			"The claim must be at least as large as the snapshot and use a StorageClass of the same CSI driver",
+		},
+	}, nil
+}
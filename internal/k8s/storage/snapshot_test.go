@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	postgresPVCs = `{"items": [
+		{"metadata": {"name": "data-postgres-0", "namespace": "db"}, "spec": {"volumeName": "pv-0", "storageClassName": "gp3", "resources": {"requests": {"storage": "20Gi"}}}, "status": {"phase": "Bound"}},
+		{"metadata": {"name": "data-postgres-1", "namespace": "db"}, "spec": {"volumeName": "pv-1", "storageClassName": "gp3", "resources": {"requests": {"storage": "20Gi"}}}, "status": {"phase": "Bound"}},
+		{"metadata": {"name": "redis", "namespace": "db"}, "spec": {"volumeName": "pv-2"}, "status": {"phase": "Bound"}}]}`
+	ebsCSIVolume       = `{"spec": {"csi": {"driver": "ebs.csi.aws.com", "volumeHandle": "vol-0abc"}}}`
+	snapshotReady      = `{"spec": {"volumeSnapshotClassName": "csi-aws", "source": {"persistentVolumeClaimName": "data"}}, "status": {"readyToUse": true, "restoreSize": "20Gi"}}`
+	gceSnapshotClasses = `{"items": [{"metadata": {"name": "csi-gce"}, "driver": "pd.csi.storage.gke.io", "deletionPolicy": "Delete"}]}`
+)
+
+func fixSnapshotTime(t *testing.T) {
+	orig := snapshotNow
+	snapshotNow = func() time.Time { return time.Date(2026, 10, 15, 12, 30, 0, 0, time.UTC) }
+	t.Cleanup(func() { snapshotNow = orig })
+}
+
+func TestParseSnapshotQuery(t *testing.T) {
+	tests := []struct {
+		query, claim string
+		ok           bool
+	}{
+		{"snapshot the postgres PVC before upgrade", "postgres", true},
+		{"take a volume snapshot of pvc data", "data", true},
+		{"snapshot pvc data-postgres-0 before the upgrade", "data-postgres-0", true},
+		{"upgrade eks cluster prod to 1.30 and snapshot the postgres pvc first", "postgres", true},
+		{"list volume snapshots for pvc data", "", false},
+		{"restore snapshot data-1 into pvc data-2", "", false},
+		{"expand pvc data to 50Gi", "", false},
+	}
+	for _, tt := range tests {
+		claim, ok := ParseSnapshotQuery(tt.query)
+		if claim != tt.claim || ok != tt.ok {
+			t.Errorf("ParseSnapshotQuery(%q) = %q, %v", tt.query, claim, ok)
+		}
+	}
+}
+
+func TestParseRestoreQuery(t *testing.T) {
+	tests := []struct {
+		query, snapshot, target string
+		ok                      bool
+	}{
+		{"restore snapshot data-20261015-1230 into pvc data-restored", "data-20261015-1230", "data-restored", true},
+		{"restore pvc data from volume snapshot nightly", "nightly", "", true},
+		{"restore the snapshot nightly as a new claim data-copy", "nightly", "data-copy", true},
+		{"restore the deployment", "", "", false},
+	}
+	for _, tt := range tests {
+		snapshot, target, ok := ParseRestoreQuery(tt.query)
+		if snapshot != tt.snapshot || target != tt.target || ok != tt.ok {
+			t.Errorf("ParseRestoreQuery(%q) = %q, %q, %v", tt.query, snapshot, target, ok)
+		}
+	}
+}
+
+func TestSnapshotPVCPlanCreatesClass(t *testing.T) {
+	fixSnapshotTime(t)
+	client := &routedClient{
+		run: map[string]string{
+			"db: get pvc -o json":               postgresPVCs,
+			": get volumesnapshotclass -o json": gceSnapshotClasses,
+		},
+		objects: map[string]string{"pv/pv-0/": ebsCSIVolume, "pv/pv-1/": ebsCSIVolume},
+	}
+	plan, err := NewSubAgent(client, false).SnapshotPVCPlan(context.Background(), "postgres", "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, step := range plan.Steps {
+		ids = append(ids, step.ID)
+	}
+	if strings.Join(ids, ",") != "create-snapshotclass-ebs-csi-aws-com-snapclass,snapshot-data-postgres-0,snapshot-data-postgres-1" {
+		t.Fatalf("steps = %v", ids)
+	}
+	if !strings.Contains(plan.Steps[0].Manifest, "driver: ebs.csi.aws.com\ndeletionPolicy: Retain") {
+		t.Errorf("class manifest:\n%s", plan.Steps[0].Manifest)
+	}
+	snapshot := plan.Steps[1].Manifest
+	for _, want := range []string{"name: data-postgres-0-20261015-1230", "volumeSnapshotClassName: ebs-csi-aws-com-snapclass", "persistentVolumeClaimName: data-postgres-0"} {
+		if !strings.Contains(snapshot, want) {
+			t.Errorf("snapshot manifest missing %q:\n%s", want, snapshot)
+		}
+	}
+	if !strings.Contains(strings.Join(plan.Notes, "\n"), "kubectl wait volumesnapshot/data-postgres-0-20261015-1230 volumesnapshot/data-postgres-1-20261015-1230 -n db") {
+		t.Errorf("notes = %v", plan.Notes)
+	}
+}
+
+func TestSnapshotPVCPlanUsesDefaultClass(t *testing.T) {
+	classes := []VolumeSnapshotClassInfo{
+		{Name: "ebs-a", Driver: "ebs.csi.aws.com"},
+		{Name: "ebs-default", Driver: "ebs.csi.aws.com", IsDefault: true},
+		{Name: "pd", Driver: "pd.csi.storage.gke.io", IsDefault: true},
+	}
+	if got := chooseSnapshotClass(classes, "ebs.csi.aws.com"); got != "ebs-default" {
+		t.Errorf("chooseSnapshotClass = %q", got)
+	}
+	if got := chooseSnapshotClass(classes[:1], "ebs.csi.aws.com"); got != "ebs-a" {
+		t.Errorf("chooseSnapshotClass = %q", got)
+	}
+}
+
+func TestSnapshotPVCPlanCloudFallback(t *testing.T) {
+	fixSnapshotTime(t)
+	tests := []struct {
+		pv   string
+		want string
+	}{
+		{ebsCSIVolume, "aws ec2 create-snapshot --volume-id vol-0abc --tag-specifications ResourceType=snapshot,Tags=[{Key=Name,Value=data-20261015-1230}"},
+		{`{"spec": {"awsElasticBlockStore": {"volumeID": "aws://us-east-1a/vol-0def"}}}`, "aws ec2 create-snapshot --volume-id vol-0def"},
+		{`{"spec": {"csi": {"driver": "pd.csi.storage.gke.io", "volumeHandle": "projects/shop/zones/europe-west1-b/disks/pvc-123"}}}`,
+			"gcloud compute snapshots create data-20261015-1230 --source-disk pvc-123 --source-disk-zone europe-west1-b --project shop"},
+		{`{"metadata": {"labels": {"topology.kubernetes.io/zone": "us-central1-a"}}, "spec": {"gcePersistentDisk": {"pdName": "disk-1"}}}`,
+			"gcloud compute snapshots create data-20261015-1230 --source-disk disk-1 --source-disk-zone us-central1-a"},
+	}
+	for _, tt := range tests {
+		// No snapshot CRDs: the volumesnapshotclass list fails
+		client := &routedClient{objects: map[string]string{
+			"pvc/data/db": `{"metadata": {"name": "data", "namespace": "db"}, "spec": {"volumeName": "pv-data"}, "status": {"phase": "Bound"}}`,
+			"pv/pv-data/": tt.pv,
+		}}
+		plan, err := NewSubAgent(client, false).SnapshotPVCPlan(context.Background(), "data", "db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		step := plan.Steps[0]
+		if got := step.Command + " " + strings.Join(step.Args, " "); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("step = %s\nwant prefix %s", got, tt.want)
+		}
+	}
+
+	client := &routedClient{objects: map[string]string{
+		"pvc/data/db": `{"metadata": {"name": "data", "namespace": "db"}, "spec": {"volumeName": "pv-data"}, "status": {"phase": "Bound"}}`,
+		"pv/pv-data/": `{"spec": {"nfs": {"server": "nas", "path": "/data"}}}`,
+	}}
+	if _, err := NewSubAgent(client, false).SnapshotPVCPlan(context.Background(), "data", "db"); err == nil || !strings.Contains(err.Error(), "no VolumeSnapshot support") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRestoreSnapshotPlan(t *testing.T) {
+	client := &routedClient{objects: map[string]string{
+		"volumesnapshot/nightly/db": snapshotReady,
+		"pvc/data/db":               boundPVC,
+	}}
+	agent := NewSubAgent(client, false)
+	plan, err := agent.RestoreSnapshotPlan(context.Background(), "nightly", "", "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := plan.Steps[0].Manifest
+	for _, want := range []string{"name: data-restored", "storage: 20Gi", "storageClassName: gp3",
+		"dataSource:\n    name: nightly\n    kind: VolumeSnapshot\n    apiGroup: snapshot.storage.k8s.io"} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("manifest missing %q:\n%s", want, manifest)
+		}
+	}
+
+	if _, err := agent.RestoreSnapshotPlan(context.Background(), "nightly", "data", "db"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("restore over existing claim: err = %v", err)
+	}
+	client.objects["volumesnapshot/nightly/db"] = strings.Replace(snapshotReady, `"readyToUse": true`, `"readyToUse": false`, 1)
+	if _, err := agent.RestoreSnapshotPlan(context.Background(), "nightly", "", "db"); err == nil || !strings.Contains(err.Error(), "not ready") {
+		t.Errorf("unready snapshot: err = %v", err)
+	}
+}
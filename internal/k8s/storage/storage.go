@@ -6,8 +6,12 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("k8s.storage")
+
 // SubAgent handles storage-related queries delegated from the main K8s agent
 type SubAgent struct {
 	client    K8sClient
@@ -43,10 +47,7 @@ type QueryAnalysis struct {
 func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
 	analysis := s.analyzeQuery(query)
 
-	if s.debug {
-		fmt.Printf("[storage] query analysis: type=%s op=%s name=%s ns=%s readonly=%v\n",
-			analysis.ResourceType, analysis.Operation, analysis.ResourceName, analysis.Namespace, analysis.IsReadOnly)
-	}
+	logger.Debugf("query analysis: type=%s op=%s name=%s ns=%s readonly=%v", analysis.ResourceType, analysis.Operation, analysis.ResourceName, analysis.Namespace, analysis.IsReadOnly)
 
 	// Use namespace from query analysis or options
 	namespace := analysis.Namespace
@@ -57,6 +58,38 @@ func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOpti
 		namespace = "default"
 	}
 
+	if name, size, ok := ParseExpandQuery(query); ok {
+		plan, err := s.ExpandPVCPlan(ctx, name, namespace, size)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Type: ResponseTypePlan, Plan: plan, Message: plan.Summary}, nil
+	}
+	if plan, ok, err := s.SnapshotPlanForQuery(ctx, query, namespace); ok {
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Type: ResponseTypePlan, Plan: plan, Message: plan.Summary}, nil
+	}
+	if snapshot, target, ok := ParseRestoreQuery(query); ok {
+		plan, err := s.RestoreSnapshotPlan(ctx, snapshot, target, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Type: ResponseTypePlan, Plan: plan, Message: plan.Summary}, nil
+	}
+	if name, ok := ParsePendingQuery(query); ok {
+		diagnoses, err := s.DiagnosePVCs(ctx, name, namespace)
+		if err != nil {
+			return nil, err
+		}
+		// Fixes change the cluster, so they come back as a plan to approve
+		if plan := PVCFixPlan(diagnoses); plan != nil {
+			return &Response{Type: ResponseTypePlan, Plan: plan, Message: plan.Summary}, nil
+		}
+		return &Response{Type: ResponseTypeResult, Data: diagnoses}, nil
+	}
+
 	// Handle read-only operations immediately
 	if analysis.IsReadOnly {
 		return s.handleReadOperation(ctx, analysis, namespace, opts)
@@ -79,6 +112,18 @@ func (s *SubAgent) handleReadOperation(ctx context.Context, analysis QueryAnalys
 		return s.handleConfigMapReadOp(ctx, analysis, namespace, opts)
 	case ResourceSecret:
 		return s.handleSecretReadOp(ctx, analysis, namespace, opts)
+	case ResourceVolumeSnapshotClass:
+		classes, err := s.ListVolumeSnapshotClasses(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Type: ResponseTypeResult, Data: classes}, nil
+	case ResourceVolumeSnapshot:
+		snapshots, err := s.ListVolumeSnapshots(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Type: ResponseTypeResult, Data: snapshots}, nil
 	default:
 		// If no specific type detected, list all storage resources
 		return s.listAllStorageResources(ctx, namespace, opts)
@@ -358,6 +403,9 @@ func (s *SubAgent) handleModifyOperation(ctx context.Context, query string, anal
 		return s.handleConfigMapModifyOp(ctx, query, analysis, namespace)
 	case ResourceSecret:
 		return s.handleSecretModifyOp(ctx, query, analysis, namespace)
+	case ResourceVolumeSnapshot, ResourceVolumeSnapshotClass:
+		// Snapshot and restore requests were matched before this point
+		return nil, fmt.Errorf("name the claim to snapshot (\"snapshot pvc data\") or the snapshot to restore (\"restore snapshot data-20240101-1200 into pvc data-restored\")")
 	default:
 		return nil, fmt.Errorf("unable to determine resource type for modification from query: %s", query)
 	}
@@ -516,6 +564,8 @@ func (s *SubAgent) detectResourceType(query string) ResourceType {
 		resourceType ResourceType
 		patterns     []string
 	}{
+		{ResourceVolumeSnapshotClass, []string{"volumesnapshotclass", "snapshot class"}},
+		{ResourceVolumeSnapshot, []string{"volumesnapshot", "snapshot"}},
 		{ResourceStorageClass, []string{"storageclass", "sc ", "storage class"}},
 		{ResourcePVC, []string{"pvc", "persistentvolumeclaim", "volume claim"}},
 		{ResourcePV, []string{"pv ", "persistentvolume", "persistent volume"}},
@@ -25,6 +25,9 @@ const (
 	ResourceStorageClass ResourceType = "storageclass"
 	ResourceConfigMap    ResourceType = "configmap"
 	ResourceSecret       ResourceType = "secret"
+
+	ResourceVolumeSnapshot      ResourceType = "volumesnapshot"
+	ResourceVolumeSnapshotClass ResourceType = "volumesnapshotclass"
 )
 
 // ResponseType indicates the type of response from the sub-agent
@@ -197,6 +200,9 @@ type CreatePVCOptions struct {
 	Labels           map[string]string
 	Selector         map[string]string
 	VolumeName       string
+
+	// DataSourceSnapshot names a VolumeSnapshot to restore the claim from
+	DataSourceSnapshot string
 }
 
 // CreateStorageClassOptions contains options for creating a StorageClass
@@ -0,0 +1,544 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Capacity thresholds, as percentages of allocatable unless noted
+const (
+	poolRequestWarning     = 85.0 // pool requests above this need more nodes soon
+	poolRequestCritical    = 95.0
+	overcommitWarning      = 1.5  // node limits / allocatable
+	consolidationThreshold = 30.0 // node requests below this on both resources
+	idleRequestThreshold   = 25.0 // namespace usage / requests below this is over-reserved
+	minIdleCPURequest      = 500  // millicores; ignore tiny namespaces
+	minIdleMemRequest      = 1 << 30
+)
+
+// nodePoolLabels are checked in order to find the pool a node belongs to
+var nodePoolLabels = []string{
+	"eks.amazonaws.com/nodegroup",
+	"alpha.eksctl.io/nodegroup-name",
+	"karpenter.sh/nodepool",
+	"cloud.google.com/gke-nodepool",
+	"kubernetes.azure.com/agentpool",
+	"agentpool",
+	"doks.digitalocean.com/node-pool",
+}
+
+type capacityNodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec struct {
+			Unschedulable bool `json:"unschedulable"`
+		} `json:"spec"`
+		Status struct {
+			Allocatable map[string]string `json:"allocatable"`
+			Conditions  []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type capacityContainer struct {
+	Resources struct {
+		Requests map[string]string `json:"requests"`
+		Limits   map[string]string `json:"limits"`
+	} `json:"resources"`
+}
+
+type capacityPodList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName       string              `json:"nodeName"`
+			Containers     []capacityContainer `json:"containers"`
+			InitContainers []capacityContainer `json:"initContainers"`
+		} `json:"spec"`
+		Status struct {
+			Phase      string `json:"phase"`
+			Conditions []struct {
+				Type    string `json:"type"`
+				Status  string `json:"status"`
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// podDemand is a pod's effective cpu/memory requests and limits
+type podDemand struct {
+	cpuRequest, cpuLimit int64
+	memRequest, memLimit int64
+}
+
+// GetCapacityReport builds a cluster-wide capacity and bin-packing report.
+// Usage comes from metrics-server when available; requests, limits, and
+// unschedulable pods only need read access to nodes and pods.
+func (m *MetricsManager) GetCapacityReport(ctx context.Context) (*CapacityReport, error) {
+	nodeData, err := m.client.RunJSON(ctx, "get", "nodes")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	var nodeList capacityNodeList
+	if err := json.Unmarshal(nodeData, &nodeList); err != nil {
+		return nil, fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	podData, err := m.client.RunJSON(ctx, "get", "pods", "--all-namespaces")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	var podList capacityPodList
+	if err := json.Unmarshal(podData, &podList); err != nil {
+		return nil, fmt.Errorf("failed to parse pods: %w", err)
+	}
+
+	report := &CapacityReport{Timestamp: time.Now()}
+
+	nodeIndex := map[string]int{}
+	for _, item := range nodeList.Items {
+		node := NodeCapacity{
+			Name:         item.Metadata.Name,
+			Pool:         nodePool(item.Metadata.Labels),
+			InstanceType: item.Metadata.Labels["node.kubernetes.io/instance-type"],
+			Cordoned:     item.Spec.Unschedulable,
+		}
+		for _, cond := range item.Status.Conditions {
+			if cond.Type == "Ready" {
+				node.Ready = cond.Status == "True"
+			}
+		}
+		node.Resources.CPU.Allocatable = parseCPUToMillicores(item.Status.Allocatable["cpu"])
+		node.Resources.Memory.Allocatable = parseMemoryToBytes(item.Status.Allocatable["memory"])
+		node.Resources.PodCapacity, _ = strconv.Atoi(item.Status.Allocatable["pods"])
+		nodeIndex[node.Name] = len(report.Nodes)
+		report.Nodes = append(report.Nodes, node)
+	}
+
+	namespaces := map[string]*NamespaceCapacity{}
+	for _, pod := range podList.Items {
+		if pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+			continue
+		}
+		demand := effectiveDemand(pod.Spec.Containers, pod.Spec.InitContainers)
+
+		if pod.Spec.NodeName == "" {
+			for _, cond := range pod.Status.Conditions {
+				if cond.Type == "PodScheduled" && cond.Status == "False" && cond.Reason == "Unschedulable" {
+					report.Unschedulable = append(report.Unschedulable, UnschedulablePod{
+						Namespace:  pod.Metadata.Namespace,
+						Name:       pod.Metadata.Name,
+						Message:    cond.Message,
+						CPURequest: demand.cpuRequest,
+						MemRequest: demand.memRequest,
+					})
+				}
+			}
+			continue
+		}
+
+		if i, ok := nodeIndex[pod.Spec.NodeName]; ok {
+			res := &report.Nodes[i].Resources
+			res.Pods++
+			res.CPU.Requests += demand.cpuRequest
+			res.CPU.Limits += demand.cpuLimit
+			res.Memory.Requests += demand.memRequest
+			res.Memory.Limits += demand.memLimit
+		}
+
+		ns := namespaces[pod.Metadata.Namespace]
+		if ns == nil {
+			ns = &NamespaceCapacity{Namespace: pod.Metadata.Namespace}
+			namespaces[pod.Metadata.Namespace] = ns
+		}
+		ns.Pods++
+		ns.CPU.Requests += demand.cpuRequest
+		ns.CPU.Limits += demand.cpuLimit
+		ns.Memory.Requests += demand.memRequest
+		ns.Memory.Limits += demand.memLimit
+	}
+
+	m.attachCapacityUsage(ctx, report, nodeIndex, namespaces)
+
+	for _, ns := range namespaces {
+		ns.CPU.UsageOfRequests = percent(ns.CPU.Usage, ns.CPU.Requests)
+		ns.Memory.UsageOfRequests = percent(ns.Memory.Usage, ns.Memory.Requests)
+		report.Namespaces = append(report.Namespaces, *ns)
+	}
+	sort.Slice(report.Namespaces, func(i, j int) bool {
+		if report.Namespaces[i].CPU.Requests != report.Namespaces[j].CPU.Requests {
+			return report.Namespaces[i].CPU.Requests > report.Namespaces[j].CPU.Requests
+		}
+		return report.Namespaces[i].Namespace < report.Namespaces[j].Namespace
+	})
+
+	pools := map[string]*PoolCapacity{}
+	for i := range report.Nodes {
+		node := &report.Nodes[i]
+		node.Resources.finalize()
+
+		addBreakdown(&report.Cluster, node.Resources)
+		pool := pools[node.Pool]
+		if pool == nil {
+			pool = &PoolCapacity{Name: node.Pool}
+			pools[node.Pool] = pool
+		}
+		pool.Nodes++
+		addBreakdown(&pool.Resources, node.Resources)
+	}
+	report.Cluster.finalize()
+	for _, pool := range pools {
+		pool.Resources.finalize()
+		report.NodePools = append(report.NodePools, *pool)
+	}
+	sort.Slice(report.NodePools, func(i, j int) bool { return report.NodePools[i].Name < report.NodePools[j].Name })
+	sort.Slice(report.Nodes, func(i, j int) bool {
+		if report.Nodes[i].Pool != report.Nodes[j].Pool {
+			return report.Nodes[i].Pool < report.Nodes[j].Pool
+		}
+		return report.Nodes[i].Name < report.Nodes[j].Name
+	})
+
+	var maxCPU, maxMem int64
+	for _, node := range report.Nodes {
+		if node.Resources.CPU.Allocatable > maxCPU {
+			maxCPU = node.Resources.CPU.Allocatable
+		}
+		if node.Resources.Memory.Allocatable > maxMem {
+			maxMem = node.Resources.Memory.Allocatable
+		}
+	}
+	for i := range report.Unschedulable {
+		p := &report.Unschedulable[i]
+		p.FitsNoNode = p.CPURequest > maxCPU || p.MemRequest > maxMem
+	}
+
+	report.Recommendations = capacityRecommendations(report)
+	return report, nil
+}
+
+// attachCapacityUsage fills usage from `kubectl top`. Any failure leaves
+// UsageAvailable false and the report falls back to requests and limits.
+func (m *MetricsManager) attachCapacityUsage(ctx context.Context, report *CapacityReport, nodeIndex map[string]int, namespaces map[string]*NamespaceCapacity) {
+	nodeOutput, err := m.client.Run(ctx, "top", "nodes", "--no-headers")
+	if err != nil {
+		logger.Debugf("capacity: usage unavailable: %v", err)
+		return
+	}
+	podOutput, err := m.client.Run(ctx, "top", "pods", "--all-namespaces", "--no-headers")
+	if err != nil {
+		logger.Debugf("capacity: pod usage unavailable: %v", err)
+		return
+	}
+	report.UsageAvailable = true
+
+	for _, n := range parseTopNodesOutput(nodeOutput) {
+		if i, ok := nodeIndex[n.Name]; ok {
+			report.Nodes[i].Resources.CPU.Usage = parseCPUToMillicores(n.CPUUsage)
+			report.Nodes[i].Resources.Memory.Usage = parseMemoryToBytes(n.MemUsage)
+		}
+	}
+	for _, p := range parseTopPodsOutput(podOutput, "") {
+		if ns, ok := namespaces[p.Namespace]; ok {
+			ns.CPU.Usage += parseCPUToMillicores(p.CPUUsage)
+			ns.Memory.Usage += parseMemoryToBytes(p.MemUsage)
+		}
+	}
+}
+
+// capacityRecommendations turns the numbers into actionable findings,
+// most severe first
+func capacityRecommendations(report *CapacityReport) []CapacityRecommendation {
+	var recs []CapacityRecommendation
+
+	for _, pool := range report.NodePools {
+		for _, r := range []struct {
+			name string
+			f    CapacityFigures
+		}{{"CPU", pool.Resources.CPU}, {"memory", pool.Resources.Memory}} {
+			if r.f.RequestPercent < poolRequestWarning {
+				continue
+			}
+			severity := "warning"
+			if r.f.RequestPercent >= poolRequestCritical {
+				severity = "critical"
+			}
+			recs = append(recs, CapacityRecommendation{
+				Severity: severity,
+				Scope:    "pool",
+				Target:   pool.Name,
+				Message: fmt.Sprintf("node pool %s is %.0f%% %s-requested; consider scaling it out or adding a larger instance type",
+					pool.Name, r.f.RequestPercent, r.name),
+			})
+		}
+	}
+
+	if len(report.Unschedulable) > 0 {
+		tooBig := 0
+		for _, p := range report.Unschedulable {
+			if p.FitsNoNode {
+				tooBig++
+				recs = append(recs, CapacityRecommendation{
+					Severity: "critical",
+					Scope:    "pod",
+					Target:   p.Namespace + "/" + p.Name,
+					Message: fmt.Sprintf("pod %s/%s requests %s CPU / %s memory, more than any node can allocate; lower its requests or add a larger node type",
+						p.Namespace, p.Name, formatMillicores(p.CPURequest), formatBytes(p.MemRequest)),
+				})
+			}
+		}
+		if rest := len(report.Unschedulable) - tooBig; rest > 0 {
+			recs = append(recs, CapacityRecommendation{
+				Severity: "critical",
+				Scope:    "pod",
+				Target:   "cluster",
+				Message:  fmt.Sprintf("%d pod(s) are unschedulable; add nodes or free requested capacity (see Unschedulable pods)", rest),
+			})
+		}
+	}
+
+	for _, node := range report.Nodes {
+		for _, r := range []struct {
+			name string
+			f    CapacityFigures
+		}{{"CPU", node.Resources.CPU}, {"memory", node.Resources.Memory}} {
+			if r.f.OvercommitRatio >= overcommitWarning {
+				recs = append(recs, CapacityRecommendation{
+					Severity: "warning",
+					Scope:    "node",
+					Target:   node.Name,
+					Message: fmt.Sprintf("node %s %s limits are %.1fx allocatable; under load pods will be throttled or OOM-killed",
+						node.Name, r.name, r.f.OvercommitRatio),
+				})
+			}
+		}
+	}
+
+	// Bin-packing: lightly requested nodes in pools with room elsewhere
+	poolNodes := map[string]int{}
+	for _, pool := range report.NodePools {
+		poolNodes[pool.Name] = pool.Nodes
+	}
+	for _, node := range report.Nodes {
+		if node.Cordoned || poolNodes[node.Pool] < 2 {
+			continue
+		}
+		if node.Resources.CPU.RequestPercent < consolidationThreshold && node.Resources.Memory.RequestPercent < consolidationThreshold {
+			recs = append(recs, CapacityRecommendation{
+				Severity: "info",
+				Scope:    "node",
+				Target:   node.Name,
+				Message: fmt.Sprintf("node %s is only %.0f%% CPU / %.0f%% memory requested; its pods could be packed onto other %s nodes",
+					node.Name, node.Resources.CPU.RequestPercent, node.Resources.Memory.RequestPercent, node.Pool),
+			})
+		}
+	}
+
+	if report.UsageAvailable {
+		for _, ns := range report.Namespaces {
+			idleCPU := ns.CPU.Requests >= minIdleCPURequest && ns.CPU.UsageOfRequests < idleRequestThreshold
+			idleMem := ns.Memory.Requests >= minIdleMemRequest && ns.Memory.UsageOfRequests < idleRequestThreshold
+			if !idleCPU && !idleMem {
+				continue
+			}
+			recs = append(recs, CapacityRecommendation{
+				Severity: "info",
+				Scope:    "namespace",
+				Target:   ns.Namespace,
+				Message: fmt.Sprintf("namespace %s uses %.0f%% of its CPU and %.0f%% of its memory requests; right-size requests to free %s CPU / %s memory",
+					ns.Namespace, ns.CPU.UsageOfRequests, ns.Memory.UsageOfRequests,
+					formatMillicores(ns.CPU.Requests-ns.CPU.Usage), formatBytes(ns.Memory.Requests-ns.Memory.Usage)),
+			})
+		}
+	}
+
+	rank := map[string]int{"critical": 0, "warning": 1, "info": 2}
+	sort.SliceStable(recs, func(i, j int) bool { return rank[recs[i].Severity] < rank[recs[j].Severity] })
+	return recs
+}
+
+// effectiveDemand follows the scheduler: a pod needs the larger of the sum
+// of its containers and its largest init container
+func effectiveDemand(containers, initContainers []capacityContainer) podDemand {
+	var d podDemand
+	for _, c := range containers {
+		d.cpuRequest += parseCPUToMillicores(c.Resources.Requests["cpu"])
+		d.cpuLimit += parseCPUToMillicores(c.Resources.Limits["cpu"])
+		d.memRequest += parseMemoryToBytes(c.Resources.Requests["memory"])
+		d.memLimit += parseMemoryToBytes(c.Resources.Limits["memory"])
+	}
+	for _, c := range initContainers {
+		d.cpuRequest = max(d.cpuRequest, parseCPUToMillicores(c.Resources.Requests["cpu"]))
+		d.cpuLimit = max(d.cpuLimit, parseCPUToMillicores(c.Resources.Limits["cpu"]))
+		d.memRequest = max(d.memRequest, parseMemoryToBytes(c.Resources.Requests["memory"]))
+		d.memLimit = max(d.memLimit, parseMemoryToBytes(c.Resources.Limits["memory"]))
+	}
+	return d
+}
+
+func nodePool(labels map[string]string) string {
+	for _, key := range nodePoolLabels {
+		if v := labels[key]; v != "" {
+			return v
+		}
+	}
+	if v := labels["node.kubernetes.io/instance-type"]; v != "" {
+		return v
+	}
+	return "default"
+}
+
+func addBreakdown(dst *CapacityBreakdown, src CapacityBreakdown) {
+	for _, pair := range []struct{ dst, src *CapacityFigures }{{&dst.CPU, &src.CPU}, {&dst.Memory, &src.Memory}} {
+		pair.dst.Allocatable += pair.src.Allocatable
+		pair.dst.Requests += pair.src.Requests
+		pair.dst.Limits += pair.src.Limits
+		pair.dst.Usage += pair.src.Usage
+	}
+	dst.Pods += src.Pods
+	dst.PodCapacity += src.PodCapacity
+}
+
+func (b *CapacityBreakdown) finalize() {
+	for _, f := range []*CapacityFigures{&b.CPU, &b.Memory} {
+		f.RequestPercent = percent(f.Requests, f.Allocatable)
+		f.UsagePercent = percent(f.Usage, f.Allocatable)
+		if f.Allocatable > 0 {
+			f.OvercommitRatio = float64(f.Limits) / float64(f.Allocatable)
+		}
+	}
+}
+
+func percent(part, whole int64) float64 {
+	if whole <= 0 {
+		return 0
+	}
+	return float64(part) / float64(whole) * 100
+}
+
+// WriteCapacityTable renders a capacity report as aligned tables
+func WriteCapacityTable(out io.Writer, report *CapacityReport) {
+	if report == nil {
+		fmt.Fprintln(out, "No capacity data.")
+		return
+	}
+	usageNote := "usage from metrics-server"
+	if !report.UsageAvailable {
+		usageNote = "usage unavailable: metrics-server not installed"
+	}
+	fmt.Fprintf(out, "Cluster capacity (%d nodes, %d pods, %s)\n\n", len(report.Nodes), report.Cluster.Pods, usageNote)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESOURCE\tALLOCATABLE\tREQUESTED\tLIMITS\tUSED")
+	c := report.Cluster
+	fmt.Fprintf(tw, "cpu\t%s\t%s (%.0f%%)\t%s (%.0f%%)\t%s\n", formatMillicores(c.CPU.Allocatable),
+		formatMillicores(c.CPU.Requests), c.CPU.RequestPercent, formatMillicores(c.CPU.Limits), c.CPU.OvercommitRatio*100,
+		usageCell(report.UsageAvailable, formatMillicores(c.CPU.Usage), c.CPU.UsagePercent))
+	fmt.Fprintf(tw, "memory\t%s\t%s (%.0f%%)\t%s (%.0f%%)\t%s\n", formatBytes(c.Memory.Allocatable),
+		formatBytes(c.Memory.Requests), c.Memory.RequestPercent, formatBytes(c.Memory.Limits), c.Memory.OvercommitRatio*100,
+		usageCell(report.UsageAvailable, formatBytes(c.Memory.Usage), c.Memory.UsagePercent))
+	tw.Flush()
+
+	if len(report.NodePools) > 0 {
+		fmt.Fprintln(out, "\nNode pools")
+		tw = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "POOL\tNODES\tPODS\tCPU REQ\tCPU LIM\tCPU USED\tMEM REQ\tMEM LIM\tMEM USED")
+		for _, p := range report.NodePools {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", p.Name, p.Nodes, podsCell(p.Resources), percentCells(p.Resources, report.UsageAvailable))
+		}
+		tw.Flush()
+	}
+
+	if len(report.Nodes) > 0 {
+		fmt.Fprintln(out, "\nNodes")
+		tw = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NODE\tPOOL\tPODS\tCPU REQ\tCPU LIM\tCPU USED\tMEM REQ\tMEM LIM\tMEM USED")
+		for _, n := range report.Nodes {
+			name := n.Name
+			if !n.Ready {
+				name += " (NotReady)"
+			} else if n.Cordoned {
+				name += " (cordoned)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, n.Pool, podsCell(n.Resources), percentCells(n.Resources, report.UsageAvailable))
+		}
+		tw.Flush()
+	}
+
+	if len(report.Namespaces) > 0 {
+		fmt.Fprintln(out, "\nNamespaces")
+		tw = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAMESPACE\tPODS\tCPU REQ\tCPU LIM\tCPU USED\tMEM REQ\tMEM LIM\tMEM USED")
+		for _, ns := range report.Namespaces {
+			cpuUsed, memUsed := "-", "-"
+			if report.UsageAvailable {
+				cpuUsed = fmt.Sprintf("%s (%.0f%% of req)", formatMillicores(ns.CPU.Usage), ns.CPU.UsageOfRequests)
+				memUsed = fmt.Sprintf("%s (%.0f%% of req)", formatBytes(ns.Memory.Usage), ns.Memory.UsageOfRequests)
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", ns.Namespace, ns.Pods,
+				formatMillicores(ns.CPU.Requests), formatMillicores(ns.CPU.Limits), cpuUsed,
+				formatBytes(ns.Memory.Requests), formatBytes(ns.Memory.Limits), memUsed)
+		}
+		tw.Flush()
+	}
+
+	if len(report.Unschedulable) > 0 {
+		fmt.Fprintln(out, "\nUnschedulable pods")
+		tw = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "POD\tCPU REQ\tMEM REQ\tREASON")
+		for _, p := range report.Unschedulable {
+			fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\n", p.Namespace, p.Name, formatMillicores(p.CPURequest), formatBytes(p.MemRequest), p.Message)
+		}
+		tw.Flush()
+	}
+
+	if len(report.Recommendations) > 0 {
+		fmt.Fprintln(out, "\nRecommendations")
+		for _, r := range report.Recommendations {
+			fmt.Fprintf(out, "  [%s] %s\n", strings.ToUpper(r.Severity), r.Message)
+		}
+	}
+}
+
+func podsCell(b CapacityBreakdown) string {
+	if b.PodCapacity > 0 {
+		return fmt.Sprintf("%d/%d", b.Pods, b.PodCapacity)
+	}
+	return strconv.Itoa(b.Pods)
+}
+
+func percentCells(b CapacityBreakdown, usageAvailable bool) string {
+	cpuUsed, memUsed := "-", "-"
+	if usageAvailable {
+		cpuUsed = fmt.Sprintf("%.0f%%", b.CPU.UsagePercent)
+		memUsed = fmt.Sprintf("%.0f%%", b.Memory.UsagePercent)
+	}
+	return fmt.Sprintf("%.0f%%\t%.0f%%\t%s\t%.0f%%\t%.0f%%\t%s",
+		b.CPU.RequestPercent, b.CPU.OvercommitRatio*100, cpuUsed,
+		b.Memory.RequestPercent, b.Memory.OvercommitRatio*100, memUsed)
+}
+
+func usageCell(available bool, value string, pct float64) string {
+	if !available {
+		return "-"
+	}
+	return fmt.Sprintf("%s (%.0f%%)", value, pct)
+}
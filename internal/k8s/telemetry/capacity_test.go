@@ -0,0 +1,153 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// capacityMockClient answers node/pod listings and kubectl top separately
+type capacityMockClient struct {
+	mockK8sClient
+	nodes, pods       string
+	topNodes, topPods string
+}
+
+func (m *capacityMockClient) RunJSON(ctx context.Context, args ...string) ([]byte, error) {
+	if args[1] == "nodes" {
+		return []byte(m.nodes), nil
+	}
+	return []byte(m.pods), nil
+}
+
+func (m *capacityMockClient) Run(ctx context.Context, args ...string) (string, error) {
+	if m.topNodes == "" {
+		return "", errors.New("metrics API not available")
+	}
+	if args[1] == "nodes" {
+		return m.topNodes, nil
+	}
+	return m.topPods, nil
+}
+
+const capacityNodesJSON = `{"items": [
+  {"metadata": {"name": "ng-a-1", "labels": {"eks.amazonaws.com/nodegroup": "general"}},
+   "status": {"allocatable": {"cpu": "2", "memory": "8Gi", "pods": "29"}, "conditions": [{"type": "Ready", "status": "True"}]}},
+  {"metadata": {"name": "ng-a-2", "labels": {"eks.amazonaws.com/nodegroup": "general"}},
+   "status": {"allocatable": {"cpu": "2", "memory": "8Gi", "pods": "29"}, "conditions": [{"type": "Ready", "status": "True"}]}}
+]}`
+
+const capacityPodsJSON = `{"items": [
+  {"metadata": {"name": "api-1", "namespace": "prod"},
+   "spec": {"nodeName": "ng-a-1", "containers": [{"resources": {"requests": {"cpu": "1800m", "memory": "2Gi"}, "limits": {"cpu": "4", "memory": "4Gi"}}}]},
+   "status": {"phase": "Running"}},
+  {"metadata": {"name": "api-2", "namespace": "prod"},
+   "spec": {"nodeName": "ng-a-2", "containers": [{"resources": {"requests": {"cpu": "1700m", "memory": "1Gi"}}}],
+            "initContainers": [{"resources": {"requests": {"cpu": "100m", "memory": "3Gi"}}}]},
+   "status": {"phase": "Running"}},
+  {"metadata": {"name": "done", "namespace": "batch"},
+   "spec": {"nodeName": "ng-a-1", "containers": [{"resources": {"requests": {"cpu": "2"}}}]},
+   "status": {"phase": "Succeeded"}},
+  {"metadata": {"name": "big", "namespace": "ml"},
+   "spec": {"containers": [{"resources": {"requests": {"cpu": "8", "memory": "32Gi"}}}]},
+   "status": {"phase": "Pending", "conditions": [{"type": "PodScheduled", "status": "False", "reason": "Unschedulable",
+     "message": "0/2 nodes are available: 2 Insufficient cpu."}]}}
+]}`
+
+func TestGetCapacityReport(t *testing.T) {
+	client := &capacityMockClient{
+		nodes:    capacityNodesJSON,
+		pods:     capacityPodsJSON,
+		topNodes: "ng-a-1   400m   20%   3Gi   37%\nng-a-2   300m   15%   2Gi   25%\n",
+		topPods:  "prod   api-1   300m   1Gi\nprod   api-2   200m   512Mi\n",
+	}
+	report, err := NewMetricsManager(client, false).GetCapacityReport(context.Background())
+	if err != nil {
+		t.Fatalf("GetCapacityReport: %v", err)
+	}
+
+	if !report.UsageAvailable {
+		t.Error("expected usage to be available")
+	}
+	if len(report.NodePools) != 1 || report.NodePools[0].Name != "general" || report.NodePools[0].Nodes != 2 {
+		t.Fatalf("node pools = %+v", report.NodePools)
+	}
+	pool := report.NodePools[0].Resources
+	if pool.CPU.Allocatable != 4000 || pool.CPU.Requests != 3500 {
+		t.Errorf("pool cpu = %+v", pool.CPU)
+	}
+	if pool.CPU.RequestPercent < 87 || pool.CPU.RequestPercent > 88 {
+		t.Errorf("pool cpu request percent = %.1f", pool.CPU.RequestPercent)
+	}
+	// api-2's init container needs 3Gi, more than its 1Gi app container
+	if pool.Memory.Requests != 5<<30 {
+		t.Errorf("pool memory requests = %d, want 5Gi", pool.Memory.Requests)
+	}
+	if report.Nodes[0].Resources.CPU.OvercommitRatio != 2 {
+		t.Errorf("ng-a-1 overcommit = %.2f", report.Nodes[0].Resources.CPU.OvercommitRatio)
+	}
+	if report.Nodes[0].Resources.Pods != 1 {
+		t.Errorf("succeeded pods should not count: %d", report.Nodes[0].Resources.Pods)
+	}
+
+	if len(report.Namespaces) != 1 || report.Namespaces[0].CPU.Usage != 500 {
+		t.Errorf("namespaces = %+v", report.Namespaces)
+	}
+	if len(report.Unschedulable) != 1 || !report.Unschedulable[0].FitsNoNode {
+		t.Errorf("unschedulable = %+v", report.Unschedulable)
+	}
+
+	var messages []string
+	for _, r := range report.Recommendations {
+		messages = append(messages, r.Severity+": "+r.Message)
+	}
+	joined := strings.Join(messages, "\n")
+	for _, want := range []string{
+		"critical: pod ml/big requests 8.0 CPU",
+		"warning: node pool general is 88% CPU-requested",
+		"warning: node ng-a-1 CPU limits are 2.0x allocatable",
+		"info: namespace prod uses 14% of its CPU",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("missing recommendation %q in:\n%s", want, joined)
+		}
+	}
+	if !strings.HasPrefix(messages[0], "critical") {
+		t.Errorf("recommendations not ordered by severity:\n%s", joined)
+	}
+}
+
+func TestGetCapacityReportWithoutMetricsServer(t *testing.T) {
+	client := &capacityMockClient{nodes: capacityNodesJSON, pods: capacityPodsJSON}
+	report, err := NewMetricsManager(client, false).GetCapacityReport(context.Background())
+	if err != nil {
+		t.Fatalf("GetCapacityReport: %v", err)
+	}
+	if report.UsageAvailable {
+		t.Error("usage should be unavailable")
+	}
+	for _, r := range report.Recommendations {
+		if r.Scope == "namespace" {
+			t.Errorf("right-sizing needs usage data: %s", r.Message)
+		}
+	}
+
+	var out bytes.Buffer
+	WriteCapacityTable(&out, report)
+	for _, want := range []string{"usage unavailable", "Node pools", "general", "Unschedulable pods", "ml/big", "Recommendations"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("table missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestAnalyzeQueryCapacityScope(t *testing.T) {
+	agent := NewSubAgent(&mockK8sClient{}, false)
+	for _, q := range []string{"how much capacity is left on my nodes", "are any pods unschedulable", "show node overcommit"} {
+		if got := agent.analyzeQuery(q).Scope; got != ScopeCapacity {
+			t.Errorf("analyzeQuery(%q) scope = %s, want capacity", q, got)
+		}
+	}
+}
@@ -84,9 +84,7 @@ func (m *MetricsManager) GetNodeMetrics(ctx context.Context) ([]NodeMetrics, err
 	// Get allocatable resources for each node
 	nodeInfo, err := m.getNodeAllocatable(ctx)
 	if err != nil {
-		if m.debug {
-			fmt.Printf("[telemetry] warning: could not get node allocatable: %v\n", err)
-		}
+		logger.Debugf("warning: could not get node allocatable: %v", err)
 	} else {
 		for i := range nodes {
 			if info, ok := nodeInfo[nodes[i].Name]; ok {
@@ -480,14 +478,15 @@ func parseMemoryToBytes(mem string) int64 {
 
 	for suffix, mult := range multipliers {
 		if strings.HasSuffix(mem, suffix) {
-			val, _ := strconv.ParseInt(strings.TrimSuffix(mem, suffix), 10, 64)
-			return val * mult
+			// Specs may use fractional quantities such as 1.5Gi
+			val, _ := strconv.ParseFloat(strings.TrimSuffix(mem, suffix), 64)
+			return int64(val * float64(mult))
 		}
 	}
 
-	// Try parsing as bytes
-	val, _ := strconv.ParseInt(mem, 10, 64)
-	return val
+	// Try parsing as bytes, including exponent forms like 1e9
+	val, _ := strconv.ParseFloat(mem, 64)
+	return int64(val)
 }
 
 // formatMillicores formats millicores to a readable string
@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"strings"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("k8s.telemetry")
+
 // SubAgent handles telemetry and metrics queries
 type SubAgent struct {
 	client  K8sClient
@@ -24,15 +28,11 @@ func NewSubAgent(client K8sClient, debug bool) *SubAgent {
 
 // HandleQuery processes a telemetry query and returns the result
 func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[telemetry] handling query: %s\n", query)
-	}
+	logger.Debugf("handling query: %s", query)
 
 	analysis := s.analyzeQuery(query)
 
-	if s.debug {
-		fmt.Printf("[telemetry] analysis: scope=%s, target=%s\n", analysis.Scope, analysis.Target)
-	}
+	logger.Debugf("analysis: scope=%s, target=%s", analysis.Scope, analysis.Target)
 
 	// Apply options from analysis if not already set
 	if opts.Scope == "" {
@@ -60,6 +60,8 @@ func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOpti
 		return s.handlePodMetrics(ctx, opts)
 	case ScopeContainer:
 		return s.handleContainerMetrics(ctx, opts)
+	case ScopeCapacity:
+		return s.handleCapacity(ctx)
 	default:
 		// Default to cluster-wide metrics
 		return s.handleClusterMetrics(ctx, opts)
@@ -81,8 +83,11 @@ func (s *SubAgent) analyzeQuery(query string) queryAnalysis {
 		Scope: ScopeCluster, // default
 	}
 
-	// Detect scope from keywords
-	if containsAny(q, []string{"node", "nodes"}) {
+	// Detect scope from keywords. Capacity questions mention nodes and pods
+	// too, so they are checked first.
+	if containsAny(q, []string{"capacity", "bin-pack", "binpack", "bin pack", "overcommit", "headroom", "unschedulable"}) {
+		analysis.Scope = ScopeCapacity
+	} else if containsAny(q, []string{"node", "nodes"}) {
 		analysis.Scope = ScopeNode
 	} else if containsAny(q, []string{"container", "containers"}) {
 		analysis.Scope = ScopeContainer
@@ -284,6 +289,25 @@ func (s *SubAgent) handleContainerMetrics(ctx context.Context, opts QueryOptions
 	}, nil
 }
 
+// handleCapacity returns the cluster capacity and bin-packing report
+func (s *SubAgent) handleCapacity(ctx context.Context) (*Response, error) {
+	report, err := s.metrics.GetCapacityReport(ctx)
+	if err != nil {
+		return &Response{
+			Type:    ResponseTypeError,
+			Message: fmt.Sprintf("Failed to build capacity report: %v", err),
+			Error:   err,
+		}, nil
+	}
+
+	return &Response{
+		Type: ResponseTypeResult,
+		Data: report,
+		Message: fmt.Sprintf("Capacity: %d nodes, CPU %.0f%% requested, Memory %.0f%% requested, %d unschedulable pods",
+			len(report.Nodes), report.Cluster.CPU.RequestPercent, report.Cluster.Memory.RequestPercent, len(report.Unschedulable)),
+	}, nil
+}
+
 // CheckMetricsServerAvailable checks if metrics-server is available
 func (s *SubAgent) CheckMetricsServerAvailable(ctx context.Context) bool {
 	return s.metrics.CheckMetricsServerAvailable(ctx)
@@ -14,6 +14,7 @@ const (
 	ScopeNamespace MetricsScope = "namespace"
 	ScopePod       MetricsScope = "pod"
 	ScopeContainer MetricsScope = "container"
+	ScopeCapacity  MetricsScope = "capacity" // requests vs limits vs usage, bin-packing
 )
 
 // MetricsSource indicates where metrics data came from
@@ -186,3 +187,90 @@ type TopContainerOutput struct {
 	CPUCores  string
 	MemBytes  string
 }
+
+// CapacityReport compares what the cluster can hold with what workloads
+// request, are allowed to use, and actually use
+type CapacityReport struct {
+	Timestamp       time.Time                `json:"timestamp"`
+	UsageAvailable  bool                     `json:"usageAvailable"` // false without metrics-server
+	Cluster         CapacityBreakdown        `json:"cluster"`
+	NodePools       []PoolCapacity           `json:"nodePools"`
+	Nodes           []NodeCapacity           `json:"nodes"`
+	Namespaces      []NamespaceCapacity      `json:"namespaces"`
+	Unschedulable   []UnschedulablePod       `json:"unschedulable,omitempty"`
+	Recommendations []CapacityRecommendation `json:"recommendations,omitempty"`
+}
+
+// CapacityBreakdown holds CPU (millicores) and memory (bytes) figures for
+// a node, a node pool, or the whole cluster
+type CapacityBreakdown struct {
+	CPU         CapacityFigures `json:"cpu"`
+	Memory      CapacityFigures `json:"memory"`
+	Pods        int             `json:"pods"`
+	PodCapacity int             `json:"podCapacity,omitempty"`
+}
+
+// CapacityFigures compares requests, limits, and usage with allocatable.
+// OvercommitRatio is limits / allocatable; above 1 the node cannot honor
+// every limit at once.
+type CapacityFigures struct {
+	Allocatable     int64   `json:"allocatable"`
+	Requests        int64   `json:"requests"`
+	Limits          int64   `json:"limits"`
+	Usage           int64   `json:"usage"`
+	RequestPercent  float64 `json:"requestPercent"`
+	UsagePercent    float64 `json:"usagePercent"`
+	OvercommitRatio float64 `json:"overcommitRatio"`
+}
+
+// NodeCapacity is the capacity breakdown of one node
+type NodeCapacity struct {
+	Name         string            `json:"name"`
+	Pool         string            `json:"pool"`
+	InstanceType string            `json:"instanceType,omitempty"`
+	Ready        bool              `json:"ready"`
+	Cordoned     bool              `json:"cordoned,omitempty"`
+	Resources    CapacityBreakdown `json:"resources"`
+}
+
+// PoolCapacity aggregates the nodes of one node pool or node group
+type PoolCapacity struct {
+	Name      string            `json:"name"`
+	Nodes     int               `json:"nodes"`
+	Resources CapacityBreakdown `json:"resources"`
+}
+
+// NamespaceCapacity is what one namespace's running pods ask for and use
+type NamespaceCapacity struct {
+	Namespace string         `json:"namespace"`
+	Pods      int            `json:"pods"`
+	CPU       ResourceDemand `json:"cpu"`    // millicores
+	Memory    ResourceDemand `json:"memory"` // bytes
+}
+
+// ResourceDemand is requested, limited, and used amounts of one resource.
+// UsageOfRequests below 100 means the namespace reserves more than it uses.
+type ResourceDemand struct {
+	Requests        int64   `json:"requests"`
+	Limits          int64   `json:"limits"`
+	Usage           int64   `json:"usage"`
+	UsageOfRequests float64 `json:"usageOfRequestsPercent"`
+}
+
+// UnschedulablePod is a pending pod the scheduler could not place
+type UnschedulablePod struct {
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	Message    string `json:"message"`
+	CPURequest int64  `json:"cpuRequest"`    // millicores
+	MemRequest int64  `json:"memoryRequest"` // bytes
+	FitsNoNode bool   `json:"fitsNoNode"`    // requests exceed every node's allocatable
+}
+
+// CapacityRecommendation is one finding from the capacity analysis
+type CapacityRecommendation struct {
+	Severity string `json:"severity"` // critical, warning, info
+	Scope    string `json:"scope"`    // pool, node, namespace, pod
+	Target   string `json:"target"`
+	Message  string `json:"message"`
+}
@@ -2,9 +2,14 @@ package k8s
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/bgdnvk/clanker/internal/k8s/cluster"
+	"github.com/bgdnvk/clanker/internal/k8s/imagescan"
+	"github.com/bgdnvk/clanker/internal/k8s/policy"
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/bgdnvk/clanker/internal/k8s/workloads"
 )
 
 // ClusterType is an alias for cluster.ClusterType
@@ -33,6 +38,9 @@ const (
 	CloudProviderGCP CloudProvider = "gcp"
 	// CloudProviderAzure indicates Microsoft Azure (AKS)
 	CloudProviderAzure CloudProvider = "azure"
+	// CloudProviderOpenShift indicates Red Hat OpenShift or OKD, on any
+	// infrastructure (ROSA, ARO, bare metal)
+	CloudProviderOpenShift CloudProvider = "openshift"
 )
 
 // ResponseType indicates the type of response from the K8s agent
@@ -42,8 +50,26 @@ const (
 	ResponseTypePlan   ResponseType = "plan"
 	ResponseTypeResult ResponseType = "result"
 	ResponseTypeError  ResponseType = "error"
+	// ResponseTypeNeedsClarification means the agent could not tell what
+	// the query targets; Clarification holds the question to answer
+	ResponseTypeNeedsClarification ResponseType = "needs_clarification"
 )
 
+// Clarification fields name the QueryOptions field an answer fills
+const (
+	ClarifyContext  = "context"
+	ClarifyResource = "resource_name"
+)
+
+// Clarification is a question the agent asks instead of guessing which
+// cluster or resource a query means. Answer it by setting Field in the
+// QueryOptions and asking again; see QueryOptions.Clarify.
+type Clarification struct {
+	Question string   `json:"question"`
+	Field    string   `json:"field"`
+	Options  []string `json:"options,omitempty"`
+}
+
 // QueryOptions contains options for handling K8s queries
 type QueryOptions struct {
 	ClusterName   string
@@ -54,16 +80,80 @@ type QueryOptions struct {
 	Region        string
 	MakerMode     bool
 	Kubeconfig    string
+	Context       string // kubeconfig context; empty uses the current context
 	CloudProvider CloudProvider
+	ListOptions
+	// ResourceNames and Container are the objects and the container the
+	// question names; see QueryEntities
+	ResourceNames []string
+	Container     string
+}
+
+// ListOptions narrows and bounds pod listings on large clusters
+type ListOptions struct {
+	// LabelSelector and FieldSelector are pushed down into list calls; the
+	// field selector applies to pods only
+	LabelSelector string
+	FieldSelector string
+	// SummaryThreshold is the pod count above which pods are counted per
+	// namespace and phase instead of listed. Zero uses
+	// workloads.DefaultSummaryThreshold; negative always lists.
+	SummaryThreshold int
+}
+
+// workloadOptions carries the namespace, selectors and summary threshold
+// over to the workloads sub-agent
+func (o QueryOptions) workloadOptions() workloads.QueryOptions {
+	return workloads.QueryOptions{
+		Namespace:        o.Namespace,
+		LabelSelector:    o.LabelSelector,
+		FieldSelector:    o.FieldSelector,
+		SummaryThreshold: o.SummaryThreshold,
+		ResourceNames:    o.ResourceNames,
+		Container:        o.Container,
+	}
+}
+
+// joinSelectors ANDs two comma-separated selectors, dropping terms of b
+// that a already has
+func joinSelectors(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	have := map[string]bool{}
+	for _, term := range strings.Split(a, ",") {
+		have[strings.TrimSpace(term)] = true
+	}
+	joined := a
+	for _, term := range strings.Split(b, ",") {
+		if term = strings.TrimSpace(term); term != "" && !have[term] {
+			have[term] = true
+			joined += "," + term
+		}
+	}
+	return joined
 }
 
 // ApplyOptions contains options for applying K8s plans
 type ApplyOptions struct {
 	Debug   bool
 	DryRun  bool
-	Force   bool
+	Force   bool // apply even when manifests fail validation
+	Schema  bool // validate manifests against the built-in API types as well
 	Wait    bool
 	Timeout time.Duration
+
+	// OverridePolicy applies despite policy violations; the override is
+	// written to the audit log
+	OverridePolicy bool
+	// ApprovePolicy confirms operations the policy requires approval for
+	ApprovePolicy func([]policy.Violation) bool
+	// IgnoreDrift applies even when objects the plan changes were deleted or
+	// modified since it was generated
+	IgnoreDrift bool
 }
 
 // K8sResponse represents the response from the K8s agent
@@ -74,6 +164,11 @@ type K8sResponse struct {
 	NeedsApproval bool
 	Summary       string
 	Error         error
+	// Report is the diagnostic report Result was rendered from, when the
+	// query was a diagnosis
+	Report *sre.DiagnosticReport
+	// Clarification is set for ResponseTypeNeedsClarification
+	Clarification *Clarification
 }
 
 // K8sPlan represents an execution plan for K8s operations
@@ -102,6 +197,18 @@ type K8sPlan struct {
 	// Raw manifests to apply
 	Manifests []Manifest `json:"manifests,omitempty"`
 
+	// Schema and server-side dry-run results for Manifests, recorded when
+	// the plan is generated
+	ManifestChecks []ManifestCheck `json:"manifest_checks,omitempty"`
+
+	// Vulnerability scans of the images the manifests deploy, recorded when
+	// the plan is generated and repeated before apply
+	ImageScans []imagescan.Result `json:"image_scans,omitempty"`
+
+	// State of the existing objects the plan changes, recorded when the plan
+	// is generated and compared against the cluster again before apply
+	Observed []ObservedObject `json:"observed,omitempty"`
+
 	// Post install tasks
 	PostInstall []PostInstallTask `json:"post_install,omitempty"`
 
@@ -142,6 +249,7 @@ type KubectlCmd struct {
 	Reason    string            `json:"reason"`
 	Produces  map[string]string `json:"produces,omitempty"`
 	WaitFor   *WaitCondition    `json:"wait_for,omitempty"`
+	Timeout   string            `json:"timeout,omitempty"` // e.g. "10m"; empty uses --step-timeout
 }
 
 // WaitCondition represents a condition to wait for
@@ -175,6 +283,7 @@ type Manifest struct {
 	Namespace  string `json:"namespace,omitempty"`
 	Content    string `json:"content"`
 	Reason     string `json:"reason"`
+	Timeout    string `json:"timeout,omitempty"` // e.g. "10m"; empty uses --step-timeout
 }
 
 // PostInstallTask represents a post installation task
@@ -204,14 +313,28 @@ type HealthStatus = cluster.HealthStatus
 
 // QueryAnalysis represents the analysis of a K8s query
 type QueryAnalysis struct {
-	IsReadOnly    bool
-	Category      string
+	IsReadOnly bool
+	Category   string
+	// Confidence is the category's share of the rule scores, or the model's
+	// own confidence when ClassifiedBy is "model"
+	Confidence   float64
+	ClassifiedBy string
+	// Candidates are the categories the rules ranked, most likely first
+	Candidates    []IntentCandidate
 	Resources     []string
 	Operations    []string
 	ClusterScope  bool
 	NamespaceHint string
 }
 
+// applyCategory adjusts the analysis to its category. Granting access is a
+// change even when phrased as "let X read ...".
+func (a *QueryAnalysis) applyCategory() {
+	if a.Category == "workload_identity" {
+		a.IsReadOnly = false
+	}
+}
+
 // AIDecisionFunc is a function type for making AI decisions
 type AIDecisionFunc func(ctx context.Context, prompt string) (string, error)
 
@@ -228,6 +351,9 @@ type ClusterResources struct {
 	PVCs        []ClusterPVCInfo       `json:"persistentVolumeClaims"`
 	ConfigMaps  []ClusterConfigMapInfo `json:"configMaps"`
 	Ingresses   []ClusterIngressInfo   `json:"ingresses,omitempty"`
+	// PodSummary replaces Pods when there are more than the summary
+	// threshold
+	PodSummary *workloads.PodSummary `json:"podSummary,omitempty"`
 	// Metrics data (optional, populated when metrics-server is available)
 	NodeMetrics []ClusterNodeMetrics `json:"nodeMetrics,omitempty"`
 	PodMetrics  []ClusterPodMetrics  `json:"podMetrics,omitempty"`
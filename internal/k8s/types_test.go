@@ -395,3 +395,17 @@ func TestClusterIngressInfo(t *testing.T) {
 		t.Errorf("Rules length = %v, want 1", len(unmarshaled.Rules))
 	}
 }
+
+func TestJoinSelectors(t *testing.T) {
+	for _, tc := range [][3]string{
+		{"", "", ""},
+		{"app=web", "", "app=web"},
+		{"", "tier=api", "tier=api"},
+		{"app=web", "tier=api", "app=web,tier=api"},
+		{"app=web,tier=api", "tier=api,env=prod", "app=web,tier=api,env=prod"},
+	} {
+		if got := joinSelectors(tc[0], tc[1]); got != tc[2] {
+			t.Errorf("joinSelectors(%q, %q) = %q, want %q", tc[0], tc[1], got, tc[2])
+		}
+	}
+}
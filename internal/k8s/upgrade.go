@@ -0,0 +1,172 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s/backup"
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+	"github.com/bgdnvk/clanker/internal/k8s/storage"
+)
+
+// UpgradeIntent is a parsed "upgrade cluster X to 1.30" request
+type UpgradeIntent struct {
+	ClusterName   string
+	ClusterType   ClusterType // empty when the query names no provider
+	TargetVersion string      // major.minor
+}
+
+var (
+	upgradeVersionPattern     = regexp.MustCompile(`\bto\s+v?(\d+\.\d+)(?:\.\d+)?\b`)
+	upgradeClusterNamePattern = regexp.MustCompile(`\bcluster\s+([a-z0-9][a-z0-9._-]*)`)
+	upgradeNameClusterPattern = regexp.MustCompile(`\b([a-z0-9][a-z0-9._-]*)\s+(?:(?:eks|gke|aks|kubeadm|kubernetes|k8s)\s+)?cluster\b`)
+	upgradeProviderPattern    = regexp.MustCompile(`\b(eks|gke|aks|kubeadm)\b`)
+)
+
+// upgradeNameStopWords are words before or after "cluster" that are not a
+// cluster name
+var upgradeNameStopWords = map[string]bool{
+	"the": true, "my": true, "our": true, "this": true, "that": true, "a": true,
+	"upgrade": true, "to": true, "version": true, "kubernetes": true, "k8s": true,
+	"eks": true, "gke": true, "aks": true, "kubeadm": true,
+}
+
+// ParseUpgradeQuery recognizes Kubernetes version upgrade requests such as
+// "upgrade cluster prod to 1.30" or "upgrade the staging eks cluster to v1.31"
+func ParseUpgradeQuery(query string) (UpgradeIntent, bool) {
+	q := strings.ToLower(query)
+	if !strings.Contains(q, "upgrade") ||
+		!containsAny(q, []string{"cluster", "kubernetes", "k8s", "control plane"}) ||
+		containsAny(q, []string{"helm", "chart", "release"}) {
+		return UpgradeIntent{}, false
+	}
+
+	match := upgradeVersionPattern.FindStringSubmatch(q)
+	if match == nil {
+		return UpgradeIntent{}, false
+	}
+	intent := UpgradeIntent{TargetVersion: match[1]}
+
+	for _, pattern := range []*regexp.Regexp{upgradeClusterNamePattern, upgradeNameClusterPattern} {
+		if m := pattern.FindStringSubmatch(q); m != nil && !upgradeNameStopWords[m[1]] {
+			intent.ClusterName = m[1]
+			break
+		}
+	}
+
+	if m := upgradeProviderPattern.FindStringSubmatch(q); m != nil {
+		intent.ClusterType = ClusterType(m[1])
+	}
+
+	return intent, true
+}
+
+// UpgradeCluster upgrades a cluster to targetVersion with its registered
+// provider
+func (a *Agent) UpgradeCluster(ctx context.Context, clusterType ClusterType, clusterName, targetVersion string) error {
+	return a.clusterMgr.UpgradeCluster(ctx, clusterType, clusterName, targetVersion)
+}
+
+// generateUpgradePlan turns an upgrade request into the phases the provider
+// runs. The plan is applied with `clanker k8s upgrade`, which re-reads the
+// cluster's versions before each phase. Volume snapshots the request asks
+// for come first, and a Velero backup of every namespace opens the
+// pre-flight phase when Velero is installed.
+func (a *Agent) generateUpgradePlan(ctx context.Context, query string, opts QueryOptions, k8sPlan *K8sPlan) (*K8sPlan, error) {
+	intent, _ := ParseUpgradeQuery(query)
+	if intent.ClusterName == "" {
+		intent.ClusterName = opts.ClusterName
+	}
+	if intent.ClusterType == "" {
+		intent.ClusterType = opts.ClusterType
+	}
+	if intent.ClusterName != "" {
+		k8sPlan.ClusterName = intent.ClusterName
+	}
+	k8sPlan.ClusterType = intent.ClusterType
+
+	switch intent.ClusterType {
+	case ClusterTypeEKS, ClusterTypeGKE, ClusterTypeAKS, ClusterTypeKubeadm:
+	default:
+		k8sPlan.Summary = fmt.Sprintf("Upgrade cluster to Kubernetes %s", intent.TargetVersion)
+		k8sPlan.Warnings = append(k8sPlan.Warnings,
+			"Cluster type unknown: name the provider (eks, gke, aks or kubeadm) in the request or set kubernetes.default_type")
+		return k8sPlan, nil
+	}
+
+	name := intent.ClusterName
+	if name == "" {
+		name = "<cluster>"
+		k8sPlan.Warnings = append(k8sPlan.Warnings, "Cluster name not found in the request")
+	}
+
+	upgradePlan := plan.GenerateUpgradePlan(plan.UpgradeOptions{
+		ClusterType:   string(intent.ClusterType),
+		ClusterName:   name,
+		TargetVersion: intent.TargetVersion,
+		Backup:        a.preUpgradeBackup(ctx, name, k8sPlan),
+	})
+
+	k8sPlan.Summary = upgradePlan.Summary
+	a.addUpgradeSnapshots(ctx, query, opts, k8sPlan)
+	appendBootstrapSteps(k8sPlan, upgradePlan.Steps, string(intent.ClusterType), name)
+	k8sPlan.Notes = append(k8sPlan.Notes, upgradePlan.Notes...)
+	k8sPlan.Notes = append(k8sPlan.Notes,
+		fmt.Sprintf("Apply with: clanker k8s upgrade %s %s --version %s", intent.ClusterType, name, intent.TargetVersion))
+	k8sPlan.Warnings = append(k8sPlan.Warnings,
+		"Upgrades cannot be rolled back; back up etcd or workload state before upgrading production clusters")
+
+	return k8sPlan, nil
+}
+
+// preUpgradeBackup returns the Velero backup to take before upgrading, or
+// nil with a warning when Velero is not installed
+func (a *Agent) preUpgradeBackup(ctx context.Context, clusterName string, k8sPlan *K8sPlan) *plan.UpgradeBackup {
+	if a.backup == nil || !a.backup.Installed(ctx) {
+		k8sPlan.Warnings = append(k8sPlan.Warnings,
+			"Velero is not installed, so no backup is taken before the upgrade; ask to \"install velero\" to add one")
+		return nil
+	}
+	opts := backup.PreUpgradeBackup(clusterName)
+	return &plan.UpgradeBackup{
+		Name:      opts.Name,
+		Namespace: backup.VeleroNamespace,
+		Manifest:  backup.BackupManifest(opts),
+	}
+}
+
+// addUpgradeSnapshots adds the snapshots asked for in requests such as
+// "upgrade eks cluster prod to 1.30 and snapshot the postgres pvc first".
+// VolumeSnapshots are manifests the plan applies; cloud disk snapshots are
+// bootstrap steps ahead of the upgrade phases.
+func (a *Agent) addUpgradeSnapshots(ctx context.Context, query string, opts QueryOptions, k8sPlan *K8sPlan) {
+	if _, ok := storage.ParseSnapshotQuery(query); !ok {
+		return
+	}
+	if a.storage == nil {
+		k8sPlan.Warnings = append(k8sPlan.Warnings, "Snapshots requested but the cluster's volumes could not be read; take them before upgrading")
+		return
+	}
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	sp, _, err := a.storage.SnapshotPlanForQuery(ctx, query, namespace)
+	if err != nil {
+		k8sPlan.Warnings = append(k8sPlan.Warnings, fmt.Sprintf("Snapshots not planned: %v; take them before upgrading", err))
+		return
+	}
+	appendStorageSteps(k8sPlan, sp.Steps)
+	k8sPlan.Notes = append(k8sPlan.Notes, sp.Notes...)
+	for _, step := range sp.Steps {
+		if step.Manifest == "" && step.Command != "kubectl" {
+			k8sPlan.Notes = append(k8sPlan.Notes, "Run the disk snapshot commands and wait for them to complete before starting the upgrade")
+			break
+		}
+	}
+	if len(k8sPlan.Manifests) > 0 {
+		k8sPlan.Notes = append(k8sPlan.Notes, "Apply this plan to take the VolumeSnapshots, and start the upgrade once they are ready")
+	}
+}
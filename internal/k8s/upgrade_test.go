@@ -0,0 +1,211 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s/backup"
+	"github.com/bgdnvk/clanker/internal/k8s/storage"
+)
+
+func TestParseUpgradeQuery(t *testing.T) {
+	tests := []struct {
+		query       string
+		ok          bool
+		clusterName string
+		clusterType ClusterType
+		version     string
+	}{
+		{query: "upgrade cluster prod to 1.30", ok: true, clusterName: "prod", version: "1.30"},
+		{query: "Upgrade the staging EKS cluster to v1.31.2", ok: true, clusterName: "staging", clusterType: ClusterTypeEKS, version: "1.31"},
+		{query: "upgrade gke cluster web-01 to 1.29", ok: true, clusterName: "web-01", clusterType: ClusterTypeGKE, version: "1.29"},
+		{query: "upgrade my kubeadm cluster to 1.30", ok: true, clusterType: ClusterTypeKubeadm, version: "1.30"},
+		{query: "upgrade kubernetes to 1.30", ok: true, version: "1.30"},
+		{query: "upgrade the nginx helm release to 1.2", ok: false},
+		{query: "upgrade cluster prod", ok: false},
+		{query: "list clusters", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseUpgradeQuery(tt.query)
+		if ok != tt.ok {
+			t.Errorf("ParseUpgradeQuery(%q) ok = %v, want %v", tt.query, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if got.ClusterName != tt.clusterName || got.ClusterType != tt.clusterType || got.TargetVersion != tt.version {
+			t.Errorf("ParseUpgradeQuery(%q) = %+v, want name=%q type=%q version=%q",
+				tt.query, got, tt.clusterName, tt.clusterType, tt.version)
+		}
+	}
+}
+
+func TestCategorizeUpgradeQuery(t *testing.T) {
+	a := &Agent{}
+	if got := a.analyzeQuery("upgrade eks cluster prod to 1.30").Category; got != "cluster_upgrade" {
+		t.Errorf("category = %q, want cluster_upgrade", got)
+	}
+	if got := a.analyzeQuery("upgrade the nginx deployment image to 1.25").Category; got == "cluster_upgrade" {
+		t.Error("deployment image upgrade categorized as cluster_upgrade")
+	}
+}
+
+func TestGenerateUpgradePlan(t *testing.T) {
+	a := &Agent{}
+	query := "upgrade eks cluster prod to 1.30"
+	plan, err := a.generatePlan(context.Background(), query, a.analyzeQuery(query), QueryOptions{})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+
+	if plan.ClusterName != "prod" || plan.ClusterType != ClusterTypeEKS {
+		t.Errorf("cluster = %s/%s, want eks/prod", plan.ClusterType, plan.ClusterName)
+	}
+	if !strings.Contains(plan.Summary, "to Kubernetes 1.30") {
+		t.Errorf("summary = %q", plan.Summary)
+	}
+
+	// Phases run in order: control plane before add-ons before nodes
+	var phases []string
+	for _, cmd := range plan.Bootstrap {
+		if len(phases) == 0 || phases[len(phases)-1] != cmd.Operation {
+			phases = append(phases, cmd.Operation)
+		}
+	}
+	want := "pre-flight,control plane,add-ons,nodes,verify"
+	if strings.Join(phases, ",") != want {
+		t.Errorf("phases = %v, want %s", phases, want)
+	}
+
+	applyNote := "Apply with: clanker k8s upgrade eks prod --version 1.30"
+	found := false
+	for _, note := range plan.Notes {
+		found = found || note == applyNote
+	}
+	if !found {
+		t.Errorf("notes missing %q: %v", applyNote, plan.Notes)
+	}
+}
+
+func TestGenerateUpgradePlanUnknownType(t *testing.T) {
+	a := &Agent{}
+	query := "upgrade cluster prod to 1.30"
+	plan, err := a.generatePlan(context.Background(), query, a.analyzeQuery(query), QueryOptions{ClusterType: ClusterTypeExisting})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+	if len(plan.Bootstrap) != 0 || len(plan.Warnings) == 0 {
+		t.Errorf("expected a warning and no steps, got %+v", plan)
+	}
+}
+
+// volumeClient serves the storage sub-agent's reads from fixed objects; the
+// cluster has no snapshot CRDs
+type volumeClient struct {
+	objects map[string]string // "kind/name/ns"
+}
+
+func (c *volumeClient) Run(ctx context.Context, args ...string) (string, error) {
+	return "", errors.New("the server doesn't have a resource type")
+}
+
+func (c *volumeClient) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	return c.Run(ctx, args...)
+}
+
+func (c *volumeClient) GetJSON(ctx context.Context, resourceType, name, namespace string) ([]byte, error) {
+	if out, ok := c.objects[resourceType+"/"+name+"/"+namespace]; ok {
+		return []byte(out), nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (c *volumeClient) Describe(ctx context.Context, resourceType, name, namespace string) (string, error) {
+	return "", nil
+}
+
+func (c *volumeClient) Delete(ctx context.Context, resourceType, name, namespace string) (string, error) {
+	return "", nil
+}
+
+func (c *volumeClient) Apply(ctx context.Context, manifest string) (string, error) {
+	return "", nil
+}
+
+func TestGenerateUpgradePlanSnapshotsFirst(t *testing.T) {
+	a := &Agent{storage: storage.NewSubAgent(&volumeClient{objects: map[string]string{
+		"pvc/postgres/db": `{"metadata": {"name": "postgres", "namespace": "db"}, "spec": {"volumeName": "pv-1"}, "status": {"phase": "Bound"}}`,
+		"pv/pv-1/":        `{"spec": {"csi": {"driver": "ebs.csi.aws.com", "volumeHandle": "vol-0abc"}}}`,
+	}}, false)}
+	query := "upgrade eks cluster prod to 1.30 and snapshot the postgres pvc first"
+	plan, err := a.generatePlan(context.Background(), query, a.analyzeQuery(query), QueryOptions{Namespace: "db"})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+	if len(plan.Bootstrap) == 0 || plan.Bootstrap[0].Operation != "snapshot-postgres" ||
+		!strings.HasPrefix(plan.Bootstrap[0].Command, "aws ec2 create-snapshot --volume-id vol-0abc") {
+		t.Fatalf("first step = %+v", plan.Bootstrap)
+	}
+	if plan.Bootstrap[1].Operation != "pre-flight" {
+		t.Errorf("second step = %+v, want the upgrade pre-flight", plan.Bootstrap[1])
+	}
+
+	// Without a readable claim the upgrade still plans, with a warning
+	a.storage = storage.NewSubAgent(&volumeClient{}, false)
+	plan, err = a.generatePlan(context.Background(), query, a.analyzeQuery(query), QueryOptions{Namespace: "db"})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+	if plan.Bootstrap[0].Operation != "pre-flight" || !strings.Contains(strings.Join(plan.Warnings, "\n"), "Snapshots not planned") {
+		t.Errorf("plan = %+v", plan)
+	}
+}
+
+// veleroClient answers as a cluster with the Velero CRDs installed
+type veleroClient struct{}
+
+func (c *veleroClient) Run(ctx context.Context, args ...string) (string, error) {
+	return "customresourcedefinition.apiextensions.k8s.io/backups.velero.io", nil
+}
+
+func (c *veleroClient) RunWithNamespace(ctx context.Context, namespace string, args ...string) (string, error) {
+	return c.Run(ctx, args...)
+}
+
+func (c *veleroClient) RunJSON(ctx context.Context, args ...string) ([]byte, error) {
+	return []byte(`{"items":[]}`), nil
+}
+
+func (c *veleroClient) Apply(ctx context.Context, manifest string, namespace string) (string, error) {
+	return "", nil
+}
+
+func TestGenerateUpgradePlanBackupFirst(t *testing.T) {
+	query := "upgrade eks cluster prod to 1.30"
+
+	a := &Agent{}
+	plan, err := a.generatePlan(context.Background(), query, a.analyzeQuery(query), QueryOptions{})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+	if !strings.Contains(strings.Join(plan.Warnings, "\n"), "Velero is not installed") {
+		t.Errorf("warnings = %v", plan.Warnings)
+	}
+
+	a.backup = backup.NewSubAgent(&veleroClient{}, false)
+	plan, err = a.generatePlan(context.Background(), query, a.analyzeQuery(query), QueryOptions{})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+	first := plan.Bootstrap[0]
+	if first.Operation != "pre-flight" || first.Command != "kubectl apply -f -" || !strings.Contains(first.Reason, "Back up every namespace with Velero as pre-upgrade-prod-") {
+		t.Errorf("first step = %+v", first)
+	}
+	if strings.Contains(strings.Join(plan.Warnings, "\n"), "Velero is not installed") {
+		t.Errorf("warned about Velero although it is installed: %v", plan.Warnings)
+	}
+}
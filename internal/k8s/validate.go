@@ -0,0 +1,214 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ManifestCheck is the pre-apply validation result for one plan manifest
+type ManifestCheck struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Schema    string `json:"schema_error,omitempty"`    // offline schema validation error
+	Admission string `json:"admission_error,omitempty"` // server-side dry-run error
+	Skipped   string `json:"skipped,omitempty"`         // why the server-side dry-run did not run
+}
+
+// Failed reports whether the manifest would be rejected
+func (c ManifestCheck) Failed() bool {
+	return c.Schema != "" || c.Admission != ""
+}
+
+// String describes the failure for warnings and errors
+func (c ManifestCheck) String() string {
+	ref := c.Kind + "/" + c.Name
+	if c.Namespace != "" {
+		ref = c.Namespace + "/" + ref
+	}
+	var problems []string
+	if c.Schema != "" {
+		problems = append(problems, "schema: "+c.Schema)
+	}
+	if c.Admission != "" {
+		problems = append(problems, "admission: "+c.Admission)
+	}
+	if len(problems) == 0 {
+		return ref + ": ok"
+	}
+	return ref + ": " + strings.Join(problems, "; ")
+}
+
+// FailedChecks returns the checks that would block an apply
+func FailedChecks(checks []ManifestCheck) []ManifestCheck {
+	var failed []ManifestCheck
+	for _, c := range checks {
+		if c.Failed() {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// strictDecoder decodes the built-in kinds, rejecting unknown and duplicate
+// fields
+var strictDecoder = serializer.NewCodecFactory(scheme.Scheme, serializer.EnableStrict).UniversalDeserializer()
+
+// ValidateManifestSchema checks every document of a manifest against the
+// built-in API types without a cluster, the way kubeval does. Kinds the
+// client does not know (CRDs) are left to the server-side dry-run.
+func ValidateManifestSchema(content string) error {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(strings.NewReader(content)))
+	var errs []error
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+		if strings.TrimSpace(string(doc)) == "" {
+			continue
+		}
+
+		_, gvk, err := strictDecoder.Decode(doc, nil, nil)
+		switch {
+		case err == nil:
+		case runtime.IsNotRegisteredError(err):
+		case runtime.IsMissingKind(err), runtime.IsMissingVersion(err):
+			errs = append(errs, fmt.Errorf("apiVersion and kind are required"))
+		case gvk != nil && gvk.Kind != "":
+			errs = append(errs, fmt.Errorf("%s: %w", gvk.Kind, err))
+		default:
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckManifests validates manifests before they are applied: offline
+// schema validation when schema is set, then `kubectl apply --dry-run=server`
+// so admission webhooks, quotas and policies get their say. An unreachable
+// cluster skips the dry-run instead of failing it.
+func (c *Client) CheckManifests(ctx context.Context, manifests []Manifest, schema bool) []ManifestCheck {
+	checks := make([]ManifestCheck, 0, len(manifests))
+	for _, m := range manifests {
+		check := ManifestCheck{Kind: m.Kind, Name: m.Name, Namespace: m.Namespace}
+		if schema {
+			if err := ValidateManifestSchema(m.Content); err != nil {
+				check.Schema = err.Error()
+			}
+		}
+		if check.Schema == "" {
+			if _, err := c.ApplyDryRunServer(ctx, m.Content, m.Namespace); err != nil {
+				if isUnreachableError(err) {
+					check.Skipped = "cluster unreachable"
+				} else {
+					check.Admission = dryRunErrorMessage(err)
+				}
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+var namespaceNotFoundPattern = regexp.MustCompile(`namespaces "([^"]+)" not found`)
+
+// CheckPlanManifests runs CheckManifests on a plan's manifests. A dry-run
+// rejected only because its namespace does not exist yet is skipped when the
+// plan itself creates that namespace.
+func CheckPlanManifests(ctx context.Context, client *Client, plan *K8sPlan, schema bool) []ManifestCheck {
+	checks := client.CheckManifests(ctx, plan.Manifests, schema)
+	created := planNamespaces(plan)
+	for i, check := range checks {
+		if m := namespaceNotFoundPattern.FindStringSubmatch(check.Admission); m != nil && created[m[1]] {
+			checks[i].Admission = ""
+			checks[i].Skipped = fmt.Sprintf("namespace %s is created by the plan", m[1])
+		}
+	}
+	return checks
+}
+
+// planNamespaces returns the namespaces a plan creates
+func planNamespaces(plan *K8sPlan) map[string]bool {
+	created := make(map[string]bool)
+	for _, cmd := range plan.KubectlCmds {
+		args := cmd.Args
+		if len(args) > 0 && args[0] == "kubectl" {
+			args = args[1:]
+		}
+		if len(args) >= 3 && args[0] == "create" && (args[1] == "namespace" || args[1] == "ns") {
+			created[args[2]] = true
+		}
+	}
+	for _, m := range plan.Manifests {
+		if m.Kind == "Namespace" {
+			created[m.Name] = true
+		}
+	}
+	return created
+}
+
+// isUnreachableError reports whether kubectl failed before reaching the API
+// server, which says nothing about the manifest
+func isUnreachableError(err error) bool {
+	msg := err.Error()
+	for _, marker := range []string{
+		"Unable to connect to the server",
+		"connection refused",
+		"no configuration has been provided",
+		"i/o timeout",
+		"no such host",
+	} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// dryRunErrorMessage keeps kubectl's stderr, which carries the admission
+// message, and drops the exit status
+func dryRunErrorMessage(err error) string {
+	msg := err.Error()
+	if _, stderr, ok := strings.Cut(msg, "stderr: "); ok && strings.TrimSpace(stderr) != "" {
+		msg = stderr
+	}
+	return strings.TrimSpace(msg)
+}
+
+// previewManifestChecks records schema and dry-run results on a freshly
+// generated plan and warns about manifests an apply would reject
+func (a *Agent) previewManifestChecks(ctx context.Context, plan *K8sPlan) {
+	if plan == nil || len(plan.Manifests) == 0 || a.client == nil {
+		return
+	}
+	plan.ManifestChecks = CheckPlanManifests(ctx, a.client, plan, true)
+	if failed := FailedChecks(plan.ManifestChecks); len(failed) > 0 {
+		for _, check := range failed {
+			plan.Warnings = append(plan.Warnings, "validation failed: "+check.String())
+		}
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("%d of %d manifests failed validation; apply is blocked unless --force is given", len(failed), len(plan.Manifests)))
+	}
+}
+
+// manifestChecksError is returned when failed checks block an apply
+func manifestChecksError(failed []ManifestCheck) error {
+	lines := make([]string, 0, len(failed))
+	for _, check := range failed {
+		lines = append(lines, "  "+check.String())
+	}
+	return fmt.Errorf("%d manifests failed validation (use --force to apply anyway):\n%s", len(failed), strings.Join(lines, "\n"))
+}
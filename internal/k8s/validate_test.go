@@ -0,0 +1,138 @@
+package k8s
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx
+`
+
+func TestValidateManifestSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"valid", validDeployment, ""},
+		{"multi document", validDeployment + "---\napiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  ports:\n  - port: 80\n", ""},
+		{"unknown field", strings.Replace(validDeployment, "  selector:", "  replica: 2\n  selector:", 1), `unknown field "spec.replica"`},
+		{"wrong type", strings.Replace(validDeployment, "image: nginx", "image: [nginx]", 1), "cannot unmarshal array"},
+		{"custom resource", "apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: w\nspec:\n  anything: true\n", ""},
+		{"missing kind", "metadata:\n  name: x\n", "apiVersion and kind are required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateManifestSchema(tt.content)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// fakeKubectl puts a kubectl on PATH that rejects manifests named
+// "blocked" and manifests in the "newns" namespace like an API server would
+func fakeKubectl(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	script := `#!/bin/sh
+input=$(cat)
+case "$input" in
+*"name: blocked"*) echo 'Error from server (Forbidden): admission webhook "policy.example.com" denied the request: image tag required' >&2; exit 1;;
+*"namespace: newns"*) echo 'Error from server (NotFound): namespaces "newns" not found' >&2; exit 1;;
+esac
+echo "deployment.apps/web created (server dry run)"
+`
+	if err := os.WriteFile(filepath.Join(dir, "kubectl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCheckPlanManifests(t *testing.T) {
+	fakeKubectl(t)
+
+	blocked := strings.Replace(validDeployment, "name: web\nspec", "name: blocked\nspec", 1)
+	inNewNamespace := strings.Replace(validDeployment, "name: web\n", "name: web\n  namespace: newns\n", 1)
+	plan := &K8sPlan{
+		KubectlCmds: []KubectlCmd{{Args: []string{"create", "namespace", "newns"}}},
+		Manifests: []Manifest{
+			{Kind: "Deployment", Name: "web", Content: validDeployment},
+			{Kind: "Deployment", Name: "blocked", Content: blocked},
+			{Kind: "Deployment", Name: "web", Namespace: "newns", Content: inNewNamespace},
+			{Kind: "Deployment", Name: "typo", Content: strings.Replace(validDeployment, "  selector:", "  replica: 2\n  selector:", 1)},
+		},
+	}
+
+	checks := CheckPlanManifests(context.Background(), NewClient("", "", false), plan, true)
+	if len(checks) != 4 {
+		t.Fatalf("got %d checks, want 4", len(checks))
+	}
+	if checks[0].Failed() || checks[0].Skipped != "" {
+		t.Errorf("valid manifest: %+v", checks[0])
+	}
+	if !strings.Contains(checks[1].Admission, "denied the request: image tag required") || strings.Contains(checks[1].Admission, "exit status") {
+		t.Errorf("admission error = %q", checks[1].Admission)
+	}
+	if checks[2].Failed() || checks[2].Skipped != "namespace newns is created by the plan" {
+		t.Errorf("planned namespace: %+v", checks[2])
+	}
+	if checks[3].Schema == "" || checks[3].Admission != "" {
+		t.Errorf("schema failure should skip the dry-run: %+v", checks[3])
+	}
+
+	failed := FailedChecks(checks)
+	if len(failed) != 2 || failed[0].Name != "blocked" || failed[1].Name != "typo" {
+		t.Errorf("failed = %+v", failed)
+	}
+}
+
+func TestApplyPlanBlocksFailedManifests(t *testing.T) {
+	fakeKubectl(t)
+
+	a := &Agent{client: NewClient("", "", false)}
+	plan := &K8sPlan{Manifests: []Manifest{{
+		Kind:    "Deployment",
+		Name:    "blocked",
+		Content: strings.Replace(validDeployment, "name: web\nspec", "name: blocked\nspec", 1),
+	}}}
+
+	err := a.ApplyPlan(context.Background(), plan, ApplyOptions{})
+	if err == nil || !strings.Contains(err.Error(), "use --force") {
+		t.Fatalf("error = %v, want a blocked apply", err)
+	}
+	if len(plan.ManifestChecks) != 1 {
+		t.Errorf("checks not recorded on the plan")
+	}
+
+	// --force applies anyway; the fake kubectl rejects the real apply too
+	if err := a.ApplyPlan(context.Background(), plan, ApplyOptions{Force: true}); err == nil || strings.Contains(err.Error(), "use --force") {
+		t.Errorf("forced apply error = %v, want the apply itself to run", err)
+	}
+}
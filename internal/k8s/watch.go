@@ -0,0 +1,235 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is the polling interval used when WatchOptions.Interval is unset
+const DefaultWatchInterval = 2 * time.Second
+
+// WatchLineStatus describes how a row changed between two watch frames
+type WatchLineStatus string
+
+const (
+	WatchLineUnchanged WatchLineStatus = "unchanged"
+	WatchLineAdded     WatchLineStatus = "added"
+	WatchLineChanged   WatchLineStatus = "changed"
+	WatchLineRemoved   WatchLineStatus = "removed"
+)
+
+// WatchOptions controls a polling watch of a resource listing
+type WatchOptions struct {
+	Resource      string
+	Namespace     string
+	AllNamespaces bool
+	Interval      time.Duration
+}
+
+// WatchLine is one rendered row of a watch frame
+type WatchLine struct {
+	Key    string
+	Text   string
+	Status WatchLineStatus
+}
+
+// WatchFrame is a single poll result diffed against the previous poll
+type WatchFrame struct {
+	Sequence  int
+	Timestamp time.Time
+	Header    string
+	Lines     []WatchLine
+	Error     error
+}
+
+// Changed reports whether any row was added, changed, or removed
+func (f WatchFrame) Changed() bool {
+	for _, line := range f.Lines {
+		if line.Status != WatchLineUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// watchableResources maps natural language nouns to kubectl resource names
+var watchableResources = []struct {
+	keywords []string
+	resource string
+}{
+	{[]string{"statefulset"}, "statefulsets"},
+	{[]string{"daemonset"}, "daemonsets"},
+	{[]string{"replicaset"}, "replicasets"},
+	{[]string{"deployment"}, "deployments"},
+	{[]string{"cronjob"}, "cronjobs"},
+	{[]string{"job"}, "jobs"},
+	{[]string{"pod"}, "pods"},
+	{[]string{"ingress"}, "ingresses"},
+	{[]string{"service", "svc"}, "services"},
+	{[]string{"pvc", "persistentvolumeclaim"}, "pvc"},
+	{[]string{"hpa", "autoscaler"}, "hpa"},
+	{[]string{"event"}, "events"},
+	{[]string{"node"}, "nodes"},
+}
+
+var watchNamespacePattern = regexp.MustCompile(`(?:in|from)\s+(?:the\s+)?(?:namespace\s+([a-z0-9][a-z0-9-]*)|([a-z0-9][a-z0-9-]*)\s+namespace)`)
+
+// ParseWatchQuery extracts a watch target from a natural language query such
+// as "watch pods in namespace foo". ok is false when the query does not ask
+// to watch a known resource.
+func ParseWatchQuery(query string) (WatchOptions, bool) {
+	lower := strings.ToLower(strings.TrimSpace(query))
+	if !strings.Contains(lower, "watch") && !strings.Contains(lower, "keep an eye") && !strings.Contains(lower, "monitor") {
+		return WatchOptions{}, false
+	}
+
+	opts := WatchOptions{}
+	for _, candidate := range watchableResources {
+		if containsAny(lower, candidate.keywords) {
+			opts.Resource = candidate.resource
+			break
+		}
+	}
+	if opts.Resource == "" {
+		return WatchOptions{}, false
+	}
+
+	if match := watchNamespacePattern.FindStringSubmatch(lower); match != nil {
+		opts.Namespace = match[1]
+		if opts.Namespace == "" {
+			opts.Namespace = match[2]
+		}
+	}
+	if opts.Namespace == "" && (strings.Contains(lower, "all namespaces") || strings.Contains(lower, "every namespace")) {
+		opts.AllNamespaces = true
+	}
+	return opts, true
+}
+
+// Watch polls kubectl get for the requested resource until ctx is cancelled,
+// calling render with each frame diffed against the previous one.
+func (c *Client) Watch(ctx context.Context, opts WatchOptions, render func(WatchFrame)) error {
+	if strings.TrimSpace(opts.Resource) == "" {
+		return fmt.Errorf("watch requires a resource")
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	args := []string{"get", opts.Resource}
+	if opts.AllNamespaces {
+		args = append(args, "-A")
+	}
+
+	var previous []WatchLine
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for sequence := 1; ; sequence++ {
+		output, err := c.RunWithNamespace(ctx, opts.Namespace, args...)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		frame := WatchFrame{Sequence: sequence, Timestamp: time.Now(), Error: err}
+		if err == nil {
+			header, rows := splitWatchTable(output)
+			frame.Header = header
+			frame.Lines = DiffWatchRows(previous, rows, sequence == 1)
+			previous = rows
+		}
+		render(frame)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// splitWatchTable separates the kubectl header from its rows and keys each
+// row by its identifying columns (NAMESPACE + NAME when present).
+func splitWatchTable(output string) (string, []WatchLine) {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) == "" {
+		return "", nil
+	}
+	header := lines[0]
+	keyColumns := 1
+	if fields := strings.Fields(header); len(fields) > 1 && fields[0] == "NAMESPACE" {
+		keyColumns = 2
+	}
+
+	rows := make([]WatchLine, 0, len(lines)-1)
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < keyColumns {
+			continue
+		}
+		rows = append(rows, WatchLine{
+			Key:    strings.Join(fields[:keyColumns], "/"),
+			Text:   line,
+			Status: WatchLineUnchanged,
+		})
+	}
+	return header, rows
+}
+
+// DiffWatchRows marks each row in current as added, changed, or unchanged
+// relative to previous, and appends rows that disappeared as removed.
+// The first frame is never highlighted.
+func DiffWatchRows(previous, current []WatchLine, first bool) []WatchLine {
+	prevByKey := make(map[string]WatchLine, len(previous))
+	for _, line := range previous {
+		prevByKey[line.Key] = line
+	}
+
+	out := make([]WatchLine, 0, len(current)+len(previous))
+	seen := make(map[string]struct{}, len(current))
+	for _, line := range current {
+		seen[line.Key] = struct{}{}
+		status := WatchLineUnchanged
+		if !first {
+			prev, ok := prevByKey[line.Key]
+			switch {
+			case !ok:
+				status = WatchLineAdded
+			case watchRowSignature(prev.Text) != watchRowSignature(line.Text):
+				status = WatchLineChanged
+			}
+		}
+		out = append(out, WatchLine{Key: line.Key, Text: line.Text, Status: status})
+	}
+
+	if !first {
+		for _, line := range previous {
+			if _, ok := seen[line.Key]; !ok {
+				out = append(out, WatchLine{Key: line.Key, Text: line.Text, Status: WatchLineRemoved})
+			}
+		}
+	}
+	return out
+}
+
+// watchRowSignature ignores the AGE column so rows do not flap on every poll
+func watchRowSignature(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return ""
+	}
+	last := fields[len(fields)-1]
+	if watchAgePattern.MatchString(last) {
+		fields = fields[:len(fields)-1]
+	}
+	return strings.Join(fields, " ")
+}
+
+var watchAgePattern = regexp.MustCompile(`^(\d+[smhdy])+$`)
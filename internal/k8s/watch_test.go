@@ -0,0 +1,65 @@
+package k8s
+
+import "testing"
+
+func TestParseWatchQuery(t *testing.T) {
+	tests := []struct {
+		query     string
+		ok        bool
+		resource  string
+		namespace string
+		allNS     bool
+	}{
+		{"watch pods in namespace foo", true, "pods", "foo", false},
+		{"watch deployments in the staging namespace", true, "deployments", "staging", false},
+		{"keep an eye on services across all namespaces", true, "services", "", true},
+		{"monitor statefulsets", true, "statefulsets", "", false},
+		{"list pods in namespace foo", false, "", "", false},
+		{"watch the weather", false, "", "", false},
+	}
+	for _, tt := range tests {
+		opts, ok := ParseWatchQuery(tt.query)
+		if ok != tt.ok {
+			t.Errorf("%q: ok = %v, want %v", tt.query, ok, tt.ok)
+			continue
+		}
+		if opts.Resource != tt.resource || opts.Namespace != tt.namespace || opts.AllNamespaces != tt.allNS {
+			t.Errorf("%q: got %+v", tt.query, opts)
+		}
+	}
+}
+
+func TestDiffWatchRows(t *testing.T) {
+	_, first := splitWatchTable("NAME READY STATUS RESTARTS AGE\napi-1 1/1 Running 0 5m\ndb-0 1/1 Running 0 5m\n")
+	initial := DiffWatchRows(nil, first, true)
+	for _, line := range initial {
+		if line.Status != WatchLineUnchanged {
+			t.Fatalf("first frame should not be highlighted, got %+v", line)
+		}
+	}
+
+	_, second := splitWatchTable("NAME READY STATUS RESTARTS AGE\napi-1 0/1 CrashLoopBackOff 1 6m\nweb-1 1/1 Running 0 2s\n")
+	diff := DiffWatchRows(first, second, false)
+	want := map[string]WatchLineStatus{
+		"api-1": WatchLineChanged,
+		"web-1": WatchLineAdded,
+		"db-0":  WatchLineRemoved,
+	}
+	if len(diff) != len(want) {
+		t.Fatalf("expected %d lines, got %+v", len(want), diff)
+	}
+	for _, line := range diff {
+		if want[line.Key] != line.Status {
+			t.Errorf("%s: status = %s, want %s", line.Key, line.Status, want[line.Key])
+		}
+	}
+}
+
+func TestDiffWatchRowsIgnoresAge(t *testing.T) {
+	_, before := splitWatchTable("NAMESPACE NAME READY AGE\nfoo api 1/1 59s\n")
+	_, after := splitWatchTable("NAMESPACE NAME READY AGE\nfoo api 1/1 61s\n")
+	diff := DiffWatchRows(before, after, false)
+	if len(diff) != 1 || diff[0].Status != WatchLineUnchanged || diff[0].Key != "foo/api" {
+		t.Fatalf("age-only change should be unchanged, got %+v", diff)
+	}
+}
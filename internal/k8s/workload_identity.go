@@ -0,0 +1,152 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/k8s/plan"
+)
+
+// WorkloadIdentityIntent is a parsed "give serviceaccount X in namespace Y
+// access to S3 bucket Z" request
+type WorkloadIdentityIntent struct {
+	ServiceAccount string
+	Namespace      string
+	Bucket         string
+	ClusterType    ClusterType // eks for S3, gke for GCS, empty when the query names neither
+	ReadOnly       bool
+}
+
+var (
+	identitySAPattern        = regexp.MustCompile(`\b(?:serviceaccount|service account|sa)\s+([a-z0-9][a-z0-9.-]*)`)
+	identityNamespacePattern = regexp.MustCompile(`\bnamespace\s+([a-z0-9][a-z0-9-]*)`)
+	identityInNamespace      = regexp.MustCompile(`\bin\s+(?:the\s+)?([a-z0-9][a-z0-9-]*)\s+namespace\b`)
+	identityBucketURLPattern = regexp.MustCompile(`\b(s3|gs)://([a-z0-9][a-z0-9._-]*)`)
+	identityBucketPattern    = regexp.MustCompile(`\bbucket\s+([a-z0-9][a-z0-9._-]*)`)
+	identityS3Pattern        = regexp.MustCompile(`\bs3\b`)
+)
+
+// ParseWorkloadIdentityQuery recognizes requests to give a service account
+// access to a storage bucket
+func ParseWorkloadIdentityQuery(query string) (WorkloadIdentityIntent, bool) {
+	q := strings.ToLower(query)
+	if !containsAny(q, []string{"access", "give", "grant", "allow", "let ", "read", "write"}) {
+		return WorkloadIdentityIntent{}, false
+	}
+
+	sa := identitySAPattern.FindStringSubmatch(q)
+	if sa == nil {
+		return WorkloadIdentityIntent{}, false
+	}
+	intent := WorkloadIdentityIntent{ServiceAccount: sa[1], Namespace: "default"}
+
+	if m := identityBucketURLPattern.FindStringSubmatch(q); m != nil {
+		intent.Bucket = m[2]
+		if m[1] == "gs" {
+			intent.ClusterType = ClusterTypeGKE
+		} else {
+			intent.ClusterType = ClusterTypeEKS
+		}
+	} else if m := identityBucketPattern.FindStringSubmatch(q); m != nil {
+		intent.Bucket = m[1]
+	} else {
+		return WorkloadIdentityIntent{}, false
+	}
+
+	if intent.ClusterType == "" {
+		switch {
+		case identityS3Pattern.MatchString(q):
+			intent.ClusterType = ClusterTypeEKS
+		case containsAny(q, []string{"gcs", "cloud storage"}):
+			intent.ClusterType = ClusterTypeGKE
+		}
+	}
+
+	// "in the media namespace" first: in "namespace read from ..." the word
+	// after namespace is not a name
+	if m := identityInNamespace.FindStringSubmatch(q); m != nil {
+		intent.Namespace = m[1]
+	} else if m := identityNamespacePattern.FindStringSubmatch(q); m != nil {
+		intent.Namespace = m[1]
+	}
+
+	intent.ReadOnly = containsAny(q, []string{"read-only", "read only", "readonly", "read access", "read from"}) &&
+		!strings.Contains(q, "write")
+
+	return intent, true
+}
+
+// generateWorkloadIdentityPlan builds the IRSA (EKS) or Workload Identity
+// (GKE) steps and the annotated ServiceAccount manifest
+func (a *Agent) generateWorkloadIdentityPlan(query string, opts QueryOptions, k8sPlan *K8sPlan) (*K8sPlan, error) {
+	intent, _ := ParseWorkloadIdentityQuery(query)
+	if intent.ClusterType == "" {
+		intent.ClusterType = opts.ClusterType
+	}
+	if intent.ClusterType == "" {
+		switch opts.CloudProvider {
+		case CloudProviderAWS:
+			intent.ClusterType = ClusterTypeEKS
+		case CloudProviderGCP:
+			intent.ClusterType = ClusterTypeGKE
+		}
+	}
+	k8sPlan.ClusterType = intent.ClusterType
+
+	clusterName := opts.ClusterName
+	if m := upgradeClusterNamePattern.FindStringSubmatch(strings.ToLower(query)); m != nil && !upgradeNameStopWords[m[1]] {
+		clusterName = m[1]
+	}
+	if clusterName == "" {
+		clusterName = "<cluster>"
+		k8sPlan.Warnings = append(k8sPlan.Warnings, "Cluster name not found in the request")
+	}
+	k8sPlan.ClusterName = clusterName
+
+	switch intent.ClusterType {
+	case ClusterTypeEKS, ClusterTypeGKE:
+	default:
+		k8sPlan.Summary = fmt.Sprintf("Give service account %s/%s access to bucket %s", intent.Namespace, intent.ServiceAccount, intent.Bucket)
+		k8sPlan.Warnings = append(k8sPlan.Warnings,
+			"Workload identity is set up with IRSA on EKS or Workload Identity on GKE; say s3:// or gs:// or name the cluster type")
+		return k8sPlan, nil
+	}
+
+	project := opts.GCPProject
+	if intent.ClusterType == ClusterTypeGKE && project == "" {
+		project = "<PROJECT_ID>"
+		k8sPlan.Warnings = append(k8sPlan.Warnings, "GCP project not set; replace <PROJECT_ID> or pass --gcp-project")
+	}
+
+	identityPlan := plan.GenerateWorkloadIdentityPlan(plan.WorkloadIdentityOptions{
+		ClusterType:    string(intent.ClusterType),
+		ClusterName:    clusterName,
+		Region:         opts.Region,
+		Project:        project,
+		Namespace:      intent.Namespace,
+		ServiceAccount: intent.ServiceAccount,
+		Bucket:         intent.Bucket,
+		ReadOnly:       intent.ReadOnly,
+	})
+
+	k8sPlan.Summary = identityPlan.Summary
+	for _, step := range identityPlan.Steps {
+		// The ServiceAccount is applied as a manifest rather than a command
+		if step.ConfigChange != nil && step.Command == "kubectl" {
+			k8sPlan.Manifests = append(k8sPlan.Manifests, Manifest{
+				Kind:       "ServiceAccount",
+				APIVersion: "v1",
+				Name:       intent.ServiceAccount,
+				Namespace:  intent.Namespace,
+				Content:    step.ConfigChange.After,
+				Reason:     step.Description,
+			})
+			continue
+		}
+		appendBootstrapSteps(k8sPlan, []plan.Step{step}, string(intent.ClusterType), clusterName)
+	}
+	k8sPlan.Notes = append(k8sPlan.Notes, identityPlan.Notes...)
+
+	return k8sPlan, nil
+}
@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseWorkloadIdentityQuery(t *testing.T) {
+	tests := []struct {
+		query       string
+		ok          bool
+		sa          string
+		namespace   string
+		bucket      string
+		clusterType ClusterType
+		readOnly    bool
+	}{
+		{
+			query: "give serviceaccount uploader in namespace media access to S3 bucket media-assets",
+			ok:    true, sa: "uploader", namespace: "media", bucket: "media-assets", clusterType: ClusterTypeEKS,
+		},
+		{
+			query: "let service account reports in the analytics namespace read from gs://analytics-exports",
+			ok:    true, sa: "reports", namespace: "analytics", bucket: "analytics-exports", clusterType: ClusterTypeGKE, readOnly: true,
+		},
+		{
+			query: "grant sa backup read-only access to bucket nightly-backups",
+			ok:    true, sa: "backup", namespace: "default", bucket: "nightly-backups", readOnly: true,
+		},
+		{query: "what can service account builder do in namespace ci", ok: false},
+		{query: "list s3 buckets", ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseWorkloadIdentityQuery(tt.query)
+		if ok != tt.ok {
+			t.Errorf("ParseWorkloadIdentityQuery(%q) ok = %v, want %v", tt.query, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		want := WorkloadIdentityIntent{ServiceAccount: tt.sa, Namespace: tt.namespace, Bucket: tt.bucket, ClusterType: tt.clusterType, ReadOnly: tt.readOnly}
+		if got != want {
+			t.Errorf("ParseWorkloadIdentityQuery(%q) = %+v, want %+v", tt.query, got, want)
+		}
+	}
+}
+
+func TestWorkloadIdentityCategoryBeforeRBAC(t *testing.T) {
+	a := &Agent{}
+	analysis := a.analyzeQuery("give serviceaccount uploader in namespace media access to S3 bucket media-assets")
+	if analysis.Category != "workload_identity" {
+		t.Errorf("category = %q, want workload_identity", analysis.Category)
+	}
+	if analysis.IsReadOnly {
+		t.Error("granting access must not be read-only")
+	}
+}
+
+func TestGenerateWorkloadIdentityPlanEKS(t *testing.T) {
+	a := &Agent{}
+	query := "give serviceaccount uploader in namespace media access to S3 bucket media-assets on cluster prod"
+	plan, err := a.generatePlan(context.Background(), query, a.analyzeQuery(query), QueryOptions{Region: "us-east-1"})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+
+	if plan.ClusterName != "prod" || plan.ClusterType != ClusterTypeEKS {
+		t.Errorf("cluster = %s/%s, want eks/prod", plan.ClusterType, plan.ClusterName)
+	}
+
+	var ops []string
+	for _, cmd := range plan.Bootstrap {
+		ops = append(ops, cmd.Operation)
+	}
+	for _, want := range []string{"check-oidc-provider", "create-irsa-role", "put-bucket-policy"} {
+		if !strings.Contains(strings.Join(ops, ","), want) {
+			t.Errorf("steps %v missing %s", ops, want)
+		}
+	}
+
+	if len(plan.Manifests) != 1 {
+		t.Fatalf("manifests = %d, want 1", len(plan.Manifests))
+	}
+	sa := plan.Manifests[0]
+	if sa.Kind != "ServiceAccount" || sa.Namespace != "media" || sa.Name != "uploader" {
+		t.Errorf("manifest = %+v", sa)
+	}
+	if !strings.Contains(sa.Content, "eks.amazonaws.com/role-arn: arn:aws:iam::<ACCOUNT_ID>:role/prod-uploader-irsa") {
+		t.Errorf("manifest content:\n%s", sa.Content)
+	}
+}
+
+func TestGenerateWorkloadIdentityPlanGKE(t *testing.T) {
+	a := &Agent{}
+	query := "let service account reports in the analytics namespace read from gs://analytics-exports"
+	plan, err := a.generatePlan(context.Background(), query, a.analyzeQuery(query),
+		QueryOptions{ClusterName: "data", GCPProject: "acme-data", Region: "europe-west1"})
+	if err != nil {
+		t.Fatalf("generatePlan: %v", err)
+	}
+
+	if len(plan.Manifests) != 1 || !strings.Contains(plan.Manifests[0].Content, "iam.gke.io/gcp-service-account: reports@acme-data.iam.gserviceaccount.com") {
+		t.Errorf("manifests = %+v", plan.Manifests)
+	}
+
+	var bind string
+	for _, cmd := range plan.Bootstrap {
+		if cmd.Operation == "bind-workload-identity" {
+			bind = cmd.Command
+		}
+	}
+	if !strings.Contains(bind, "serviceAccount:acme-data.svc.id.goog[analytics/reports]") {
+		t.Errorf("bind command = %q", bind)
+	}
+}
@@ -0,0 +1,81 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// namedOperations change one existing workload, so they need its name
+var namedOperations = map[string]bool{
+	"scale":    true,
+	"restart":  true,
+	"rollback": true,
+	"delete":   true,
+	"update":   true,
+}
+
+// resolveTarget finds the workload a change applies to among those in its
+// namespace. A name given in the query or in opts that exists is used as
+// is; otherwise the workloads whose names contain it are candidates, and
+// with no name every workload of the type is. One candidate is used, and
+// several return a clarification listing them. When the listing fails or
+// nothing matches, the query's name stands and the plan says what is
+// missing.
+func (s *SubAgent) resolveTarget(ctx context.Context, analysis queryAnalysis, opts QueryOptions) (string, *Clarification) {
+	namespace := planNamespace(analysis, opts)
+	out, err := s.client.RunWithNamespace(ctx, namespace, "get", string(analysis.WorkloadType), "-o", "name")
+	if err != nil {
+		logger.Debugf("listing %ss to resolve %q failed: %v", analysis.WorkloadType, analysis.ResourceName, err)
+		return analysis.ResourceName, nil
+	}
+	names := parseNames(out)
+
+	for _, name := range append([]string{analysis.ResourceName}, opts.ResourceNames...) {
+		for _, n := range names {
+			if name != "" && n == name {
+				return name, nil
+			}
+		}
+	}
+
+	var candidates []string
+	for _, n := range names {
+		if strings.Contains(n, analysis.ResourceName) {
+			candidates = append(candidates, n)
+		}
+	}
+	switch {
+	case len(candidates) == 1:
+		logger.Debugf("resolved %s %q to %s", analysis.WorkloadType, analysis.ResourceName, candidates[0])
+		return candidates[0], nil
+	case len(candidates) > 1 && analysis.ResourceName == "":
+		return "", &Clarification{
+			Question: fmt.Sprintf("Which %s in namespace %s should I %s?", analysis.WorkloadType, namespace, analysis.Operation),
+			Options:  candidates,
+		}
+	case len(candidates) > 1:
+		return "", &Clarification{
+			Question: fmt.Sprintf("%d %ss in namespace %s match %q. Which one should I %s?", len(candidates), analysis.WorkloadType, namespace, analysis.ResourceName, analysis.Operation),
+			Options:  candidates,
+		}
+	}
+	return analysis.ResourceName, nil
+}
+
+// parseNames reads the names from "kubectl get -o name" output, dropping
+// the kind prefix of lines like "deployment.apps/web"
+func parseNames(out string) []string {
+	var names []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if i := strings.LastIndex(line, "/"); i >= 0 {
+			line = line[i+1:]
+		}
+		names = append(names, line)
+	}
+	return names
+}
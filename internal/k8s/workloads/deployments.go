@@ -322,9 +322,7 @@ func (m *DeploymentManager) parseDeploymentList(data []byte) ([]DeploymentInfo,
 	for _, item := range list.Items {
 		dep, err := m.parseDeployment(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[deployments] failed to parse deployment: %v\n", err)
-			}
+			logger.Debugf("failed to parse deployment: %v", err)
 			continue
 		}
 		deployments = append(deployments, *dep)
@@ -230,9 +230,7 @@ func (m *PodManager) parsePodList(data []byte) ([]PodInfo, error) {
 	for _, item := range list.Items {
 		pod, err := m.parsePod(item)
 		if err != nil {
-			if m.debug {
-				fmt.Printf("[pods] failed to parse pod: %v\n", err)
-			}
+			logger.Debugf("failed to parse pod: %v", err)
 			continue
 		}
 		pods = append(pods, *pod)
@@ -0,0 +1,162 @@
+package workloads
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// DefaultSummaryThreshold is the pod count above which listings switch to
+// counts per namespace and phase, unless QueryOptions sets its own
+const DefaultSummaryThreshold = 2000
+
+// podSummaryColumns prints only the two fields a summary needs, so counting
+// tens of thousands of pods stays far smaller than their JSON
+const podSummaryColumns = "custom-columns=NAMESPACE:.metadata.namespace,PHASE:.status.phase"
+
+// PodSummary counts pods per namespace and phase
+type PodSummary struct {
+	Total int `json:"total"`
+	// Namespaces maps namespace to phase to pod count. Summaries of a
+	// kubectl table count the STATUS column, such as CrashLoopBackOff,
+	// in place of the phase.
+	Namespaces map[string]map[string]int `json:"namespaces"`
+}
+
+// NewPodSummary returns an empty summary
+func NewPodSummary() *PodSummary {
+	return &PodSummary{Namespaces: map[string]map[string]int{}}
+}
+
+// Add counts one pod in namespace with phase
+func (s *PodSummary) Add(namespace, phase string) {
+	if phase == "" || phase == "<none>" {
+		phase = "Unknown"
+	}
+	if s.Namespaces[namespace] == nil {
+		s.Namespaces[namespace] = map[string]int{}
+	}
+	s.Namespaces[namespace][phase]++
+	s.Total++
+}
+
+// SummaryLimit returns the pod count above which pods are summarized:
+// opts.SummaryThreshold, or DefaultSummaryThreshold when it is zero.
+// Negative never summarizes.
+func (opts QueryOptions) SummaryLimit() int {
+	if opts.SummaryThreshold == 0 {
+		return DefaultSummaryThreshold
+	}
+	return opts.SummaryThreshold
+}
+
+// SummarizePods counts the pods in namespace (or every namespace) that
+// match the selectors in opts
+func SummarizePods(ctx context.Context, client K8sClient, namespace string, opts QueryOptions) (*PodSummary, error) {
+	args := []string{"get", "pods", "-o", podSummaryColumns, "--no-headers"}
+	if opts.LabelSelector != "" {
+		args = append(args, "-l", opts.LabelSelector)
+	}
+	if opts.FieldSelector != "" {
+		args = append(args, "--field-selector", opts.FieldSelector)
+	}
+
+	var output string
+	var err error
+	if opts.AllNamespaces {
+		output, err = client.Run(ctx, append(args, "-A")...)
+	} else {
+		output, err = client.RunWithNamespace(ctx, namespace, args...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pods: %w", err)
+	}
+	return ParsePodSummary(output), nil
+}
+
+// ParsePodSummary counts the NAMESPACE and PHASE rows SummarizePods lists
+func ParsePodSummary(output string) *PodSummary {
+	summary := NewPodSummary()
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		phase := ""
+		if len(fields) > 1 {
+			phase = fields[1]
+		}
+		summary.Add(fields[0], phase)
+	}
+	return summary
+}
+
+// SummarizePodTable counts the pods in a kubectl get pods table, such as
+// the -o wide listing, so a listing can be summarized without listing the
+// pods again. Tables without a NAMESPACE column are counted in namespace.
+// It returns nil when output is not a pod table, such as "No resources
+// found".
+func SummarizePodTable(output, namespace string) *PodSummary {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	nsCol, statusCol := -1, -1
+	for i, column := range strings.Fields(lines[0]) {
+		switch column {
+		case "NAMESPACE":
+			nsCol = i
+		case "STATUS":
+			statusCol = i
+		}
+	}
+	if statusCol < 0 {
+		return nil
+	}
+
+	summary := NewPodSummary()
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) <= statusCol || len(fields) <= nsCol {
+			continue
+		}
+		ns := namespace
+		if nsCol >= 0 {
+			ns = fields[nsCol]
+		}
+		summary.Add(ns, fields[statusCol])
+	}
+	return summary
+}
+
+// String renders the summary as a table with a column per phase
+func (s *PodSummary) String() string {
+	phaseSet := map[string]bool{}
+	namespaces := make([]string, 0, len(s.Namespaces))
+	for ns, phases := range s.Namespaces {
+		namespaces = append(namespaces, ns)
+		for phase := range phases {
+			phaseSet[phase] = true
+		}
+	}
+	sort.Strings(namespaces)
+	phases := make([]string, 0, len(phaseSet))
+	for phase := range phaseSet {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "NAMESPACE\t%s\tTOTAL\n", strings.ToUpper(strings.Join(phases, "\t")))
+	for _, ns := range namespaces {
+		row := []string{ns}
+		total := 0
+		for _, phase := range phases {
+			row = append(row, fmt.Sprintf("%d", s.Namespaces[ns][phase]))
+			total += s.Namespaces[ns][phase]
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\n", strings.Join(row, "\t"), total)
+	}
+	_ = w.Flush()
+	return fmt.Sprintf("%d pods in %d namespaces\n%s", s.Total, len(namespaces), b.String())
+}
@@ -46,6 +46,9 @@ type ResponseType string
 const (
 	ResponseTypeResult ResponseType = "result"
 	ResponseTypePlan   ResponseType = "plan"
+	// ResponseTypeNeedsClarification asks which workload the query meant
+	// instead of guessing; see Response.Clarification
+	ResponseTypeNeedsClarification ResponseType = "needs_clarification"
 )
 
 // QueryOptions contains options for workload queries
@@ -54,6 +57,14 @@ type QueryOptions struct {
 	LabelSelector string
 	FieldSelector string
 	AllNamespaces bool
+	// SummaryThreshold is the pod count above which pod listings become
+	// counts per namespace and phase; see SummaryLimit
+	SummaryThreshold int
+	// ResourceNames and Container come from the question when the caller
+	// extracted them; the first name is used when the query analysis
+	// finds none
+	ResourceNames []string
+	Container     string
 }
 
 // Response represents the response from the workloads sub-agent
@@ -62,6 +73,15 @@ type Response struct {
 	Data    interface{}
 	Plan    *WorkloadPlan
 	Message string
+	// Clarification is set for ResponseTypeNeedsClarification
+	Clarification *Clarification
+}
+
+// Clarification is a question to answer before a plan can be made. The
+// answer goes back in QueryOptions.ResourceNames.
+type Clarification struct {
+	Question string
+	Options  []string
 }
 
 // WorkloadPlan represents a plan for workload modifications
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/log"
 )
 
+var logger = log.New("k8s.workloads")
+
 // SubAgent handles workload-related operations
 type SubAgent struct {
 	client K8sClient
@@ -23,23 +27,35 @@ func NewSubAgent(client K8sClient, debug bool) *SubAgent {
 
 // HandleQuery processes workload-related queries
 func (s *SubAgent) HandleQuery(ctx context.Context, query string, opts QueryOptions) (*Response, error) {
-	if s.debug {
-		fmt.Printf("[workloads] handling query: %s\n", query)
-	}
+	logger.Debugf("handling query: %s", query)
 
 	// Analyze the query
 	analysis := s.analyzeQuery(query)
-
-	if s.debug {
-		fmt.Printf("[workloads] analysis: readonly=%v, workloadType=%s, operation=%s\n",
-			analysis.IsReadOnly, analysis.WorkloadType, analysis.Operation)
+	if analysis.ResourceName == "" && len(opts.ResourceNames) > 0 {
+		analysis.ResourceName = opts.ResourceNames[0]
 	}
 
+	logger.Debugf("analysis: readonly=%v, workloadType=%s, operation=%s", analysis.IsReadOnly, analysis.WorkloadType, analysis.Operation)
+
 	// For read-only operations, execute immediately
 	if analysis.IsReadOnly {
 		return s.executeReadOnly(ctx, query, analysis, opts)
 	}
 
+	// Changes to a named workload need to know which one; ask rather than
+	// guess when the name is missing or matches several
+	if namedOperations[analysis.Operation] {
+		name, clarification := s.resolveTarget(ctx, analysis, opts)
+		if clarification != nil {
+			return &Response{
+				Type:          ResponseTypeNeedsClarification,
+				Message:       clarification.Question,
+				Clarification: clarification,
+			}, nil
+		}
+		analysis.ResourceName = name
+	}
+
 	// For modifications, generate a plan
 	plan, err := s.generatePlan(ctx, query, analysis, opts)
 	if err != nil {
@@ -258,7 +274,7 @@ func (s *SubAgent) executeReadOnly(ctx context.Context, query string, analysis q
 				Message: "Please specify a pod name to get logs",
 			}, nil
 		}
-		return s.handleLogs(ctx, analysis.ResourceName, namespace, LogOptions{TailLines: 100})
+		return s.handleLogs(ctx, analysis.ResourceName, namespace, LogOptions{Container: opts.Container, TailLines: 100})
 	case "status":
 		return s.handleStatus(ctx, analysis.WorkloadType, analysis.ResourceName, namespace)
 	case "events":
@@ -278,16 +294,38 @@ func (s *SubAgent) handleList(ctx context.Context, workloadType WorkloadType, na
 		resourceType = "statefulsets"
 	}
 
+	args := []string{"get", resourceType, "-o", "wide"}
+	if opts.LabelSelector != "" {
+		args = append(args, "-l", opts.LabelSelector)
+	}
+	// Field selectors are resource specific; the compiled ones target pods
+	if opts.FieldSelector != "" && resourceType == "pods" {
+		args = append(args, "--field-selector", opts.FieldSelector)
+	}
+
 	if opts.AllNamespaces {
-		output, err = s.client.Run(ctx, "get", resourceType, "-A", "-o", "wide")
+		output, err = s.client.Run(ctx, append(args, "-A")...)
 	} else {
-		output, err = s.client.RunWithNamespace(ctx, namespace, "get", resourceType, "-o", "wide")
+		output, err = s.client.RunWithNamespace(ctx, namespace, args...)
 	}
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to list %s: %w", resourceType, err)
 	}
 
+	// A listing of tens of thousands of pods becomes a summary instead of a
+	// table too large to read or send to the model. The pods are counted
+	// from the listing itself; output that cannot be counted is returned as is.
+	if limit := opts.SummaryLimit(); resourceType == "pods" && limit > 0 {
+		if summary := SummarizePodTable(output, namespace); summary != nil && summary.Total > limit {
+			return &Response{
+				Type:    ResponseTypeResult,
+				Data:    summary.String(),
+				Message: fmt.Sprintf("%d pods, more than %d, summarized per namespace and status", summary.Total, limit),
+			}, nil
+		}
+	}
+
 	return &Response{
 		Type:    ResponseTypeResult,
 		Data:    output,
@@ -370,13 +408,7 @@ func (s *SubAgent) handleEvents(ctx context.Context, name, namespace string) (*R
 
 // generatePlan creates a plan for workload modifications
 func (s *SubAgent) generatePlan(ctx context.Context, query string, analysis queryAnalysis, opts QueryOptions) (*WorkloadPlan, error) {
-	namespace := opts.Namespace
-	if analysis.Namespace != "" {
-		namespace = analysis.Namespace
-	}
-	if namespace == "" {
-		namespace = "default"
-	}
+	namespace := planNamespace(analysis, opts)
 
 	plan := &WorkloadPlan{
 		Version:   1,
@@ -405,6 +437,18 @@ func (s *SubAgent) generatePlan(ctx context.Context, query string, analysis quer
 	return plan, nil
 }
 
+// planNamespace returns the namespace a change applies to: the one the
+// query names, else the configured one, else default
+func planNamespace(analysis queryAnalysis, opts QueryOptions) string {
+	if analysis.Namespace != "" {
+		return analysis.Namespace
+	}
+	if opts.Namespace != "" {
+		return opts.Namespace
+	}
+	return "default"
+}
+
 // generateCreatePlan generates a plan for creating a workload
 func (s *SubAgent) generateCreatePlan(analysis queryAnalysis, namespace string) (*WorkloadPlan, error) {
 	plan := &WorkloadPlan{
@@ -3,6 +3,7 @@ package workloads
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -25,9 +26,13 @@ type mockClient struct {
 	deleteError       error
 	logsResponse      string
 	logsError         error
+	logsPod           string
+	logsOpts          LogOptionsInternal
+	runCalls          int
 }
 
 func (m *mockClient) Run(ctx context.Context, args ...string) (string, error) {
+	m.runCalls++
 	return m.runResponse, m.runError
 }
 
@@ -56,6 +61,7 @@ func (m *mockClient) Delete(ctx context.Context, resourceType, name, namespace s
 }
 
 func (m *mockClient) Logs(ctx context.Context, podName, namespace string, opts LogOptionsInternal) (string, error) {
+	m.logsPod, m.logsOpts = podName, opts
 	return m.logsResponse, m.logsError
 }
 
@@ -482,3 +488,140 @@ func TestIsCommonWord(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePodSummary(t *testing.T) {
+	summary := ParsePodSummary("web   Running\nweb   Pending\nweb   Running\nkube-system   Running\njobs   <none>\n")
+	if summary.Total != 5 {
+		t.Fatalf("Total = %d, want 5", summary.Total)
+	}
+	if got := summary.Namespaces["web"]["Running"]; got != 2 {
+		t.Errorf("web Running = %d, want 2", got)
+	}
+	if got := summary.Namespaces["jobs"]["Unknown"]; got != 1 {
+		t.Errorf("jobs Unknown = %d, want 1", got)
+	}
+	out := summary.String()
+	if !strings.HasPrefix(out, "5 pods in 3 namespaces\n") || !strings.Contains(out, "NAMESPACE") {
+		t.Errorf("String = %q", out)
+	}
+}
+
+func TestSummarizePodTable(t *testing.T) {
+	all := SummarizePodTable(`NAMESPACE   NAME    READY   STATUS             RESTARTS      AGE   IP
+web         web-1   1/1     Running            0             2d    10.0.0.1
+web         web-2   0/1     CrashLoopBackOff   7 (2m ago)    2d    10.0.0.2
+api         api-1   1/1     Running            0             5h    10.0.0.3
+`, "")
+	if all == nil || all.Total != 3 || all.Namespaces["web"]["CrashLoopBackOff"] != 1 || all.Namespaces["api"]["Running"] != 1 {
+		t.Errorf("summary = %+v", all)
+	}
+
+	one := SummarizePodTable("NAME    READY   STATUS    RESTARTS   AGE\nweb-1   1/1     Running   0          2d\n", "web")
+	if one == nil || one.Namespaces["web"]["Running"] != 1 {
+		t.Errorf("summary without a NAMESPACE column = %+v", one)
+	}
+
+	if got := SummarizePodTable("No resources found in web namespace.\n", "web"); got != nil {
+		t.Errorf("summary of a message = %+v, want nil", got)
+	}
+}
+
+func TestHandleListSummarizesLargeListings(t *testing.T) {
+	client := &mockClient{runResponse: `NAMESPACE   NAME    READY   STATUS    RESTARTS   AGE
+web         web-1   1/1     Running   0          2d
+web         web-2   0/1     Pending   0          2d
+api         api-1   1/1     Running   0          5h
+`}
+	agent := NewSubAgent(client, false)
+
+	resp, err := agent.handleList(context.Background(), WorkloadPod, "", QueryOptions{AllNamespaces: true, SummaryThreshold: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := resp.Data.(string); !strings.HasPrefix(data, "3 pods in 2 namespaces") {
+		t.Errorf("Data = %q, want a summary", data)
+	}
+	if client.runCalls != 1 {
+		t.Errorf("kubectl ran %d times, want one listing", client.runCalls)
+	}
+
+	// Under the threshold, or with it disabled, pods are listed
+	for _, threshold := range []int{0, -1} {
+		resp, err = agent.handleList(context.Background(), WorkloadPod, "", QueryOptions{AllNamespaces: true, SummaryThreshold: threshold})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := resp.Data.(string); data != client.runResponse {
+			t.Errorf("threshold %d: Data = %q, want the listing", threshold, data)
+		}
+	}
+
+	// Output that cannot be counted is returned as is
+	client.runResponse = "No resources found\n"
+	resp, err = agent.handleList(context.Background(), WorkloadPod, "", QueryOptions{AllNamespaces: true, SummaryThreshold: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := resp.Data.(string); data != client.runResponse {
+		t.Errorf("Data = %q, want the listing", data)
+	}
+}
+
+func TestHandleQueryUsesExtractedNames(t *testing.T) {
+	client := &mockClient{logsResponse: "ready"}
+	agent := NewSubAgent(client, false)
+
+	_, err := agent.HandleQuery(context.Background(), "tail the logs for the checkout app", QueryOptions{
+		Namespace:     "shop",
+		ResourceNames: []string{"checkout-7d9f"},
+		Container:     "app",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.logsPod != "checkout-7d9f" || client.logsOpts.Container != "app" {
+		t.Errorf("Logs(%q, %+v), want pod checkout-7d9f and container app", client.logsPod, client.logsOpts)
+	}
+}
+
+func TestHandleQueryAsksWhichWorkload(t *testing.T) {
+	client := &mockClient{runWithNSResponse: "deployment.apps/web-api\ndeployment.apps/web-worker\ndeployment.apps/billing\n"}
+	agent := NewSubAgent(client, false)
+	ctx := context.Background()
+
+	resp, err := agent.HandleQuery(ctx, "restart deployment web", QueryOptions{Namespace: "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Type != ResponseTypeNeedsClarification || resp.Clarification == nil {
+		t.Fatalf("response = %+v, want a clarification", resp)
+	}
+	if got := strings.Join(resp.Clarification.Options, ","); got != "web-api,web-worker" {
+		t.Errorf("options = %s, want web-api,web-worker", got)
+	}
+
+	// The answer comes back as a resource name
+	resp, err = agent.HandleQuery(ctx, "restart deployment web", QueryOptions{Namespace: "shop", ResourceNames: []string{"web-worker"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Type != ResponseTypePlan || resp.Plan.Summary != "Restart deployment web-worker" {
+		t.Errorf("response = %+v, want a restart plan for web-worker", resp)
+	}
+
+	// A single partial match is used
+	resp, err = agent.HandleQuery(ctx, "restart deployment bill", QueryOptions{Namespace: "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Type != ResponseTypePlan || resp.Plan.Summary != "Restart deployment billing" {
+		t.Errorf("response = %+v, want a restart plan for billing", resp)
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	got := parseNames("deployment.apps/web\n\npod/web-0\nplain\n")
+	if strings.Join(got, ",") != "web,web-0,plain" {
+		t.Errorf("parseNames = %v", got)
+	}
+}
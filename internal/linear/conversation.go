@@ -1,15 +1,12 @@
 package linear
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/bgdnvk/clanker/internal/convhistory"
 )
 
 // ConversationEntry is a single Q&A turn against the Linear ask agent.
@@ -44,15 +41,12 @@ func NewConversationHistory(workspaceID string) *ConversationHistory {
 func (h *ConversationHistory) AddEntry(question, answer, workspaceID string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.Entries = append(h.Entries, ConversationEntry{
+	h.Entries = convhistory.Append(h.Entries, ConversationEntry{
 		Timestamp:   time.Now(),
 		Question:    question,
 		Answer:      answer,
 		WorkspaceID: workspaceID,
-	})
-	if len(h.Entries) > MaxHistoryEntries {
-		h.Entries = h.Entries[len(h.Entries)-MaxHistoryEntries:]
-	}
+	}, MaxHistoryEntries)
 }
 
 func (h *ConversationHistory) UpdateAccountStatus(status *AccountStatus) {
@@ -102,45 +96,18 @@ func (h *ConversationHistory) GetAccountStatusContext() string {
 	)
 }
 
-func historyPath(workspaceID string) (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-	dir := filepath.Join(home, ".clanker")
-	if err := secfile.EnsurePrivateDir(dir); err != nil {
-		return "", err
-	}
-	return filepath.Join(dir, fmt.Sprintf("linear-%s.json", secfile.SafeSlug(workspaceID))), nil
+// Save persists the conversation history to ~/.clanker/conversations/linear_<workspaceid>.json
+func (h *ConversationHistory) Save() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Entries = convhistory.FitSize(h.Entries, convhistory.MaxBytes())
+	return convhistory.Save("linear", h.WorkspaceID, h)
 }
 
+// Load loads conversation history from disk
 func (h *ConversationHistory) Load() error {
-	path, err := historyPath(h.WorkspaceID)
-	if err != nil {
-		return err
-	}
-	data, err := secfile.ReadPrivate(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	return json.Unmarshal(data, h)
-}
-
-func (h *ConversationHistory) Save() error {
-	path, err := historyPath(h.WorkspaceID)
-	if err != nil {
-		return err
-	}
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	data, err := json.MarshalIndent(h, "", "  ")
-	if err != nil {
-		return err
-	}
-	return secfile.WritePrivate(path, data)
+	_, err := convhistory.Load("linear", h.WorkspaceID, h)
+	return err
 }
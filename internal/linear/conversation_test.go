@@ -27,7 +27,7 @@ func TestConversationHistory_RoundTrip(t *testing.T) {
 		t.Fatalf("Save: %v", err)
 	}
 
-	path := filepath.Join(tmpHome, ".clanker", "linear-ws-abc.json")
+	path := filepath.Join(tmpHome, ".clanker", "conversations", "linear_ws-abc.json")
 	if _, err := os.Stat(path); err != nil {
 		t.Fatalf("expected history file at %s: %v", path, err)
 	}
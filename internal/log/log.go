@@ -0,0 +1,284 @@
+// Package log is clanker's structured logger. Each package logs through a
+// Logger named for its module ("k8s", "k8s.sre", "cloudflare.dns"), and
+// levels are set per module with a spec such as "k8s=debug,ai=info": a
+// module without its own level uses its parent's, then the default.
+// Messages go to stderr as text ("[k8s.sre] listing pods") or as JSON
+// lines, and pass through the redaction hooks before they are written.
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Levels, as slog's, plus LevelOff which silences a module
+const (
+	LevelDebug = slog.LevelDebug
+	LevelInfo  = slog.LevelInfo
+	LevelWarn  = slog.LevelWarn
+	LevelError = slog.LevelError
+	LevelOff   = slog.Level(100)
+)
+
+// Options configures logging for a run
+type Options struct {
+	// Spec is a comma-separated list of module=level pairs, plus an
+	// optional bare level for every other module: "warn,k8s=debug"
+	Spec string
+	// Format is text (the default) or json
+	Format string
+	// Debug makes debug the default level, as --debug does
+	Debug  bool
+	Output io.Writer
+}
+
+type config struct {
+	def     slog.Level
+	modules map[string]slog.Level
+	handler slog.Handler
+}
+
+var current atomic.Pointer[config]
+
+var redactors struct {
+	sync.RWMutex
+	fns []func(string) string
+}
+
+func init() {
+	current.Store(&config{def: LevelInfo, handler: newTextHandler(os.Stderr)})
+}
+
+// Configure replaces the logging setup. It is called once flags and the
+// config file are read; until then info and above go to stderr as text.
+func Configure(opts Options) error {
+	def := LevelInfo
+	if opts.Debug {
+		def = LevelDebug
+	}
+	def, modules, err := ParseSpec(opts.Spec, def)
+	if err != nil {
+		return err
+	}
+	out := opts.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	var handler slog.Handler
+	switch strings.ToLower(strings.TrimSpace(opts.Format)) {
+	case "", "text":
+		handler = newTextHandler(out)
+	case "json":
+		handler = slog.NewJSONHandler(out, &slog.HandlerOptions{
+			Level: LevelDebug,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				return redactAttr(a)
+			},
+		})
+	default:
+		return fmt.Errorf("unknown log format %q (use text or json)", opts.Format)
+	}
+	current.Store(&config{def: def, modules: modules, handler: handler})
+	return nil
+}
+
+// ParseSpec parses a level spec such as "warn,k8s=debug,ai=info" into the
+// default level, def unless the spec has a bare level, and the levels of
+// the modules it names
+func ParseSpec(spec string, def slog.Level) (slog.Level, map[string]slog.Level, error) {
+	modules := make(map[string]slog.Level)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		module, levelName, named := strings.Cut(part, "=")
+		if !named {
+			levelName = module
+		}
+		level, err := parseLevel(levelName)
+		if err != nil {
+			return def, nil, err
+		}
+		if !named {
+			def = level
+			continue
+		}
+		module = strings.ToLower(strings.TrimSpace(module))
+		if module == "" {
+			return def, nil, fmt.Errorf("missing module in %q", part)
+		}
+		modules[module] = level
+	}
+	return def, modules, nil
+}
+
+func parseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "off", "none":
+		return LevelOff, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (use debug, info, warn, error or off)", name)
+}
+
+// levelFor returns the level of module, or of its nearest parent
+func (c *config) levelFor(module string) slog.Level {
+	for m := module; m != ""; {
+		if level, ok := c.modules[m]; ok {
+			return level
+		}
+		i := strings.LastIndex(m, ".")
+		if i < 0 {
+			break
+		}
+		m = m[:i]
+	}
+	return c.def
+}
+
+// AddRedactor adds a hook that every message and string attribute passes
+// through before it is written
+func AddRedactor(fn func(string) string) {
+	redactors.Lock()
+	defer redactors.Unlock()
+	redactors.fns = append(redactors.fns, fn)
+}
+
+// Redact applies the redaction hooks to s
+func Redact(s string) string {
+	redactors.RLock()
+	defer redactors.RUnlock()
+	for _, fn := range redactors.fns {
+		s = fn(s)
+	}
+	return s
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			return slog.String(a.Key, Redact(v.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, Redact(v.String()))
+		}
+	}
+	return a
+}
+
+// Logger logs for one module. Its level is looked up on every call, so
+// package-level loggers created before Configure follow it.
+type Logger struct {
+	module string
+}
+
+// New returns the logger of module
+func New(module string) *Logger {
+	return &Logger{module: strings.ToLower(module)}
+}
+
+// Enabled reports whether the logger writes messages at level, for
+// skipping work that only feeds a log line
+func (l *Logger) Enabled(level slog.Level) bool {
+	return level >= current.Load().levelFor(l.module)
+}
+
+// Debug logs msg with slog-style key-value pairs
+func (l *Logger) Debug(msg string, args ...any) { l.log(LevelDebug, msg, args...) }
+func (l *Logger) Info(msg string, args ...any)  { l.log(LevelInfo, msg, args...) }
+func (l *Logger) Warn(msg string, args ...any)  { l.log(LevelWarn, msg, args...) }
+func (l *Logger) Error(msg string, args ...any) { l.log(LevelError, msg, args...) }
+
+// Debugf logs a printf-style message
+func (l *Logger) Debugf(format string, args ...any) { l.logf(LevelDebug, format, args...) }
+func (l *Logger) Infof(format string, args ...any)  { l.logf(LevelInfo, format, args...) }
+func (l *Logger) Warnf(format string, args ...any)  { l.logf(LevelWarn, format, args...) }
+func (l *Logger) Errorf(format string, args ...any) { l.logf(LevelError, format, args...) }
+
+func (l *Logger) logf(level slog.Level, format string, args ...any) {
+	if !l.Enabled(level) {
+		return
+	}
+	l.log(level, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) log(level slog.Level, msg string, args ...any) {
+	cfg := current.Load()
+	if level < cfg.levelFor(l.module) {
+		return
+	}
+	r := slog.NewRecord(time.Now(), level, Redact(strings.TrimRight(msg, "\n")), 0)
+	r.AddAttrs(slog.String("module", l.module))
+	r.Add(args...)
+	_ = cfg.handler.Handle(context.Background(), r)
+}
+
+// textHandler writes "[module] message key=value" lines, the shape
+// clanker's debug output has always had
+type textHandler struct {
+	mu  *sync.Mutex
+	out io.Writer
+}
+
+func newTextHandler(out io.Writer) *textHandler {
+	return &textHandler{mu: &sync.Mutex{}, out: out}
+}
+
+func (h *textHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var module string
+	var attrs strings.Builder
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "module" {
+			module = a.Value.String()
+			return true
+		}
+		a = redactAttr(a)
+		value := a.Value.String()
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&attrs, " %s=%s", a.Key, value)
+		return true
+	})
+
+	var b strings.Builder
+	if module != "" {
+		fmt.Fprintf(&b, "[%s] ", module)
+	}
+	if r.Level >= LevelWarn {
+		b.WriteString(strings.ToLower(r.Level.String()) + ": ")
+	}
+	b.WriteString(r.Message)
+	b.WriteString(attrs.String())
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.out, b.String())
+	return err
+}
+
+// WithAttrs and WithGroup are unused: loggers pass attributes per call
+func (h *textHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *textHandler) WithGroup(string) slog.Handler      { return h }
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func configure(t *testing.T, opts Options) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	opts.Output = &buf
+	if err := Configure(opts); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = Configure(Options{}) })
+	return &buf
+}
+
+func TestParseSpec(t *testing.T) {
+	def, modules, err := ParseSpec(" warn, k8s=debug ,AI=info,k8s.sre=off", LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def != LevelWarn || modules["k8s"] != LevelDebug || modules["ai"] != LevelInfo || modules["k8s.sre"] != LevelOff {
+		t.Errorf("def = %v, modules = %v", def, modules)
+	}
+	if def, _, _ := ParseSpec("", LevelDebug); def != LevelDebug {
+		t.Errorf("empty spec changed default to %v", def)
+	}
+	for _, spec := range []string{"k8s=loud", "=debug", "verbose"} {
+		if _, _, err := ParseSpec(spec, LevelInfo); err == nil {
+			t.Errorf("%q accepted", spec)
+		}
+	}
+}
+
+func TestModuleLevelsFallBackToParent(t *testing.T) {
+	buf := configure(t, Options{Spec: "warn,k8s=debug,k8s.sre=error"})
+
+	New("k8s.eks").Debugf("creating cluster %s", "prod")
+	New("k8s.sre").Warn("dropped")
+	New("k8s.sre").Error("owner lookup failed")
+	New("cloudflare.dns").Info("dropped")
+	New("cloudflare.dns").Warn("zone not found")
+
+	want := "[k8s.eks] creating cluster prod\n" +
+		"[k8s.sre] error: owner lookup failed\n" +
+		"[cloudflare.dns] warn: zone not found\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDebugSetsDefaultLevel(t *testing.T) {
+	buf := configure(t, Options{Debug: true, Spec: "iam=info"})
+	New("k8s").Debug("listing pods", "namespace", "kube system", "count", 3)
+	New("iam").Debug("dropped")
+	if got := buf.String(); got != "[k8s] listing pods namespace=\"kube system\" count=3\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	buf := configure(t, Options{Format: "json", Spec: "debug"})
+	New("k8s.sre").Debug("hpa check failed", "error", errors.New("forbidden"))
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if line["level"] != "DEBUG" || line["module"] != "k8s.sre" || line["msg"] != "hpa check failed" || line["error"] != "forbidden" {
+		t.Errorf("line = %v", line)
+	}
+
+	if err := Configure(Options{Format: "xml"}); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	mask := func(s string) string { return strings.ReplaceAll(s, "s3cr3t", "[REDACTED]") }
+	redactors.Lock()
+	saved := redactors.fns
+	redactors.fns = nil
+	redactors.Unlock()
+	t.Cleanup(func() {
+		redactors.Lock()
+		redactors.fns = saved
+		redactors.Unlock()
+	})
+	AddRedactor(mask)
+
+	buf := configure(t, Options{Spec: "debug"})
+	New("cloudflare").Debugf("token=%s", "s3cr3t")
+	New("cloudflare").Debug("request failed", "error", errors.New("bad token s3cr3t"))
+	if got := buf.String(); strings.Contains(got, "s3cr3t") || strings.Count(got, "[REDACTED]") != 2 {
+		t.Errorf("output = %q", got)
+	}
+
+	buf = configure(t, Options{Format: "json", Spec: "debug"})
+	New("cloudflare").Debug("auth", "token", "s3cr3t")
+	if strings.Contains(buf.String(), "s3cr3t") {
+		t.Errorf("json output = %q", buf.String())
+	}
+}
@@ -19,8 +19,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bgdnvk/clanker/internal/audit"
 	clankeraws "github.com/bgdnvk/clanker/internal/aws"
+	"github.com/bgdnvk/clanker/internal/dryrun"
 	"github.com/bgdnvk/clanker/internal/openclaw"
+	"github.com/bgdnvk/clanker/internal/planrun"
 	"github.com/bgdnvk/clanker/internal/resourcedb"
 	"github.com/bgdnvk/clanker/internal/wordpress"
 )
@@ -248,11 +251,22 @@ type ExecOptions struct {
 	VerdaClientSecret string
 	VerdaProjectID    string
 
+	// Sentry options (empty org and host use the client's defaults)
+	SentryAuthToken string
+	SentryOrgSlug   string
+	SentryHost      string
+
 	// Tencent Cloud options
 	TencentSecretID  string
 	TencentSecretKey string
 	TencentRegion    string
 
+	// GitHub options (empty uses the gh CLI's own login)
+	GitHubToken string
+
+	// GitLab options (empty uses the glab CLI's own login)
+	GitLabToken string
+
 	// Oracle Cloud Infrastructure options
 	OracleProfile       string
 	OracleCompartmentID string
@@ -289,6 +303,15 @@ func ExecutePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 		return fmt.Errorf("missing output writer")
 	}
 
+	// A dry run prints each changing command and leaves no checkpoint or
+	// run log behind. Lookups still run, so commands are printed with what
+	// they resolve to; values only a skipped command would produce stay as
+	// placeholders.
+	dryRun := dryrun.Enabled()
+	if dryRun {
+		opts.DisableDurableCheckpoint = true
+	}
+
 	// Validate plan sequencing and reorder if needed
 	if warnings := ValidatePlanSequencing(plan); len(warnings) > 0 {
 		for _, w := range warnings {
@@ -303,7 +326,7 @@ func ExecutePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 	var planLogger *PlanLogWriter
 	if opts.PlanLogger != nil {
 		planLogger = opts.PlanLogger
-	} else {
+	} else if !dryRun {
 		var logErr error
 		planLogger, logErr = NewPlanLogWriter("")
 		if logErr != nil {
@@ -415,11 +438,30 @@ func ExecutePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 		_, _ = fmt.Fprintf(opts.Writer, "[maker] preflight warning: %s\n", warning)
 	}
 
+	// interrupted saves the checkpoint when Ctrl-C stops the plan at
+	// command idx, so running the same apply again resumes there
+	interrupted := func(idx int) error {
+		if !opts.DisableDurableCheckpoint {
+			if persistErr := persistDurableCheckpoint(plan, opts, bindings); persistErr != nil {
+				_, _ = fmt.Fprintf(opts.Writer, "[maker][checkpoint] warning: failed to persist durable checkpoint: %v\n", persistErr)
+			}
+		}
+		if planLogger != nil {
+			planLogger.WriteEvent("interrupted", fmt.Sprintf("Interrupted at command %d/%d", idx+1, len(plan.Commands)))
+			planLogger.UpdateBindings(bindings)
+			planLogger.WriteSummary("interrupted", plan)
+		}
+		return planInterrupted(opts.Writer, idx, len(plan.Commands), !opts.DisableDurableCheckpoint)
+	}
+
 	for idx, cmdSpec := range plan.Commands {
 		if resumeFromIndex > 0 && idx < resumeFromIndex {
 			_, _ = fmt.Fprintf(opts.Writer, "[maker][checkpoint] skipping already-completed command %d/%d\n", idx+1, len(plan.Commands))
 			continue
 		}
+		if planrun.Interrupted(ctx) {
+			return interrupted(idx)
+		}
 		_, _ = fmt.Fprintf(opts.Writer, "[maker][checkpoint] start command %d/%d\n", idx+1, len(plan.Commands))
 
 		if err := validateCommand(cmdSpec.Args, opts.Destroyer); err != nil {
@@ -479,7 +521,7 @@ func ExecutePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 		args = resolveBindingAliases(args, bindings)
 
 		// AI-powered placeholder resolution with exponential backoff
-		if hasUnresolvedPlaceholders(args) {
+		if !dryRun && hasUnresolvedPlaceholders(args) {
 			before := extractUnresolvedPlaceholders(args)
 			resolved, resolveErr := maybeResolvePlaceholdersWithAI(ctx, opts, args, bindings, "")
 			if resolveErr != nil {
@@ -505,7 +547,7 @@ func ExecutePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 
 		// One-click deploy: ensure ECR repo/image bindings exist before generating EC2 user-data,
 		// since user-data injection needs ECR_URI/ACCOUNT_ID/REGION.
-		if !autoImagePrepared && shouldAutoPrepareImage(args, plan.Question, bindings, opts) {
+		if !dryRun && !autoImagePrepared && shouldAutoPrepareImage(args, plan.Question, bindings, opts) {
 			if err := autoPrepareImageForOneClickDeploy(ctx, plan.Question, args, bindings, opts); err != nil {
 				return fmt.Errorf("command %d image preparation failed: %w", idx+1, err)
 			}
@@ -525,14 +567,16 @@ func ExecutePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 		args = injectEnvVarsAsInstanceTags(args, bindings)
 
 		// For EC2 run-instances, store env vars in SSM and check SSM connectivity
-		if len(args) >= 2 && args[0] == "ec2" && args[1] == "run-instances" {
+		if !dryRun && len(args) >= 2 && args[0] == "ec2" && args[1] == "run-instances" {
 			storeEnvVarsInSSM(ctx, bindings, opts)
 			ensureSREObserverRuntimeSSMReadPolicy(ctx, args, bindings, opts)
 			args, _ = RemediateRunInstancesForSSM(ctx, opts, args, opts.Writer)
 		}
 
-		if err := maybeSyncSecretsForRunInstances(ctx, args, opts); err != nil {
-			_, _ = fmt.Fprintf(opts.Writer, "[maker] warning: failed to sync secrets for user-data: %v\n", err)
+		if !dryRun {
+			if err := maybeSyncSecretsForRunInstances(ctx, args, opts); err != nil {
+				_, _ = fmt.Fprintf(opts.Writer, "[maker] warning: failed to sync secrets for user-data: %v\n", err)
+			}
 		}
 
 		args = sanitizeCommandArgsForExecution(args, bindings)
@@ -567,7 +611,7 @@ func ExecutePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 			_, _ = fmt.Fprintf(opts.Writer, "[maker][checkpoint] success command %d/%d\n", idx+1, len(plan.Commands))
 			continue
 		}
-		if len(unresolved) > 0 {
+		if len(unresolved) > 0 && !dryRun {
 			_ = maybeSwarmDiagnose(ctx, opts, "preflight: unresolved placeholders", args, strings.Join(unresolved, ", "), bindings)
 			return fmt.Errorf("command %d has unresolved placeholders: %s", idx+1, strings.Join(unresolved, ", "))
 		}
@@ -585,12 +629,24 @@ func ExecutePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 			return fmt.Errorf("command %d rejected: %w", idx+1, err)
 		}
 
+		if dryrun.Skip(opts.Writer, "aws", awsArgs) {
+			continue
+		}
+
 		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: %s\n", idx+1, len(plan.Commands), formatAWSArgsForLog(awsArgs))
 		if planLogger != nil {
 			planLogger.RecordCommandStart(idx, args0(args), args1(args))
 		}
 
-		out, runErr := runAWSCommandStreaming(ctx, awsArgs, zipBytes, opts.Writer)
+		out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return runAWSCommandStreaming(ctx, awsArgs, zipBytes, opts.Writer)
+		})
+		if runErr != nil && planrun.Interrupted(ctx) {
+			if planLogger != nil {
+				planLogger.RecordCommandFailure(idx, args0(args), args1(args), "interrupted")
+			}
+			return interrupted(idx)
+		}
 		if runErr != nil {
 			if handled, handleErr := handleAWSFailure(ctx, plan, opts, idx, args, awsArgs, zipBytes, out, runErr, remediationAttempted, bindings, healPolicy, healRuntime); handled {
 				if handleErr != nil {
@@ -685,6 +741,9 @@ func ExecutePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 			stackName := strings.TrimSpace(flagValue(args, "--stack-name"))
 			if stackName != "" {
 				status, details, waitErr := waitForCloudFormationStackTerminal(ctx, opts, stackName, opts.Writer)
+				if waitErr != nil && planrun.Interrupted(ctx) {
+					return interrupted(idx)
+				}
 				if waitErr != nil {
 					return fmt.Errorf("cloudformation wait failed for %s: %w", stackName, waitErr)
 				}
@@ -768,6 +827,10 @@ func ExecutePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 		}
 	}
 
+	if dryRun {
+		return nil
+	}
+
 	// Post-deploy feedback loop (one-click EC2+ALB): if targets stay unhealthy, run SSM diagnostics
 	// and apply a safe bind-to-0.0.0.0 remediation automatically.
 	if err := maybeAutoFixUnhealthyALBTargets(ctx, bindings, opts, postDeployFixConfig{Aggressive: true}); err != nil {
@@ -2545,6 +2608,20 @@ func formatAWSArgsForLog(awsArgs []string) string {
 			parts = append(parts, "--user-data=<redacted>")
 			continue
 		}
+		// Never log Secrets Manager values either.
+		if lower == "--secret-string" || lower == "--secret-binary" {
+			parts = append(parts, a)
+			if i+1 < len(awsArgs) {
+				parts = append(parts, "<redacted>")
+				i++
+			}
+			continue
+		}
+		if strings.HasPrefix(lower, "--secret-string=") || strings.HasPrefix(lower, "--secret-binary=") {
+			name, _, _ := strings.Cut(trimmed, "=")
+			parts = append(parts, name+"=<redacted>")
+			continue
+		}
 		if isSSMSecureStringPut {
 			if lower == "--value" {
 				parts = append(parts, a)
@@ -2847,6 +2924,10 @@ func maybeRunLocalPlanStep(ctx context.Context, index, total int, args []string,
 		return true, fmt.Errorf("sleep duration out of range: %d", seconds)
 	}
 
+	if dryrun.Enabled() {
+		_, _ = fmt.Fprintf(w, "%s sleep %d\n", dryrun.Prefix, seconds)
+		return true, nil
+	}
 	_, _ = fmt.Fprintf(w, "[maker] running %d/%d: sleep %d\n", index, total, seconds)
 	if seconds == 0 {
 		return true, nil
@@ -4872,6 +4953,9 @@ func resolveAWSBinary() (string, []string, error) {
 
 func runAWSCommandStreaming(ctx context.Context, args []string, stdinBytes []byte, w io.Writer) (string, error) {
 	args = sanitizeAWSCLIArgs(args, w)
+	if dryrun.Skip(w, "aws", args) {
+		return "", dryrun.ErrStopped
+	}
 
 	awsBin, attempts, resolveErr := resolveAWSBinary()
 	if resolveErr != nil {
@@ -4887,16 +4971,20 @@ func runAWSCommandStreaming(ctx context.Context, args []string, stdinBytes []byt
 	stderr, _ := cmd.StderrPipe()
 
 	if err := cmd.Start(); err != nil {
+		audit.RecordStep("aws", args, err)
 		return "", fmt.Errorf("failed to start aws CLI (%s): %w", awsBin, err)
 	}
 
 	out, streamErr := streamMerged(w, stdout, stderr)
 	if streamErr != nil {
 		_ = cmd.Process.Kill()
+		audit.RecordStep("aws", args, streamErr)
 		return out, streamErr
 	}
 
-	if err := cmd.Wait(); err != nil {
+	err := cmd.Wait()
+	audit.RecordStep("aws", args, err)
+	if err != nil {
 		return out, err
 	}
 
@@ -14,6 +14,10 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/dryrun"
+	"github.com/bgdnvk/clanker/internal/planrun"
 )
 
 const clankerAzureHealthZipToken = "__CLANKER_AZURE_HEALTH_ZIP__"
@@ -42,6 +46,9 @@ func ExecuteAzurePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 	bindings := make(map[string]string)
 
 	for idx, cmdSpec := range plan.Commands {
+		if planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if err := validateAzCommand(cmdSpec.Args, opts.Destroyer); err != nil {
 			return fmt.Errorf("command %d rejected: %w", idx+1, err)
 		}
@@ -70,23 +77,34 @@ func ExecuteAzurePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 			}
 		}
 
-		if hasUnresolvedPlaceholders(args) {
+		// A dry run prints values only a skipped command would produce as placeholders
+		if hasUnresolvedPlaceholders(args) && !dryrun.Enabled() {
 			return fmt.Errorf("command %d has unresolved placeholders after substitutions", idx+1)
 		}
 
+		if dryrun.Skip(opts.Writer, "az", args) {
+			continue
+		}
+
 		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: %s\n", idx+1, len(plan.Commands), formatAzArgsForLog(args))
 
-		out, runErr := runAzCommandStreaming(ctx, args, opts.Writer)
+		// Each attempt, retries included, gets the command's timeout
+		runAz := func() (string, error) {
+			return runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+				return runAzCommandStreaming(ctx, args, opts.Writer)
+			})
+		}
+		out, runErr := runAz()
 		if runErr != nil && isAzZipDeployCommand(args) && isAzTransientDeployError(out) {
 			for attempt := 1; attempt <= 4; attempt++ {
 				backoff := time.Duration(2*attempt) * time.Second
 				_, _ = fmt.Fprintf(opts.Writer, "[maker] zipdeploy transient failure; retrying in %s (attempt %d/4)\n", backoff.String(), attempt)
 				select {
 				case <-ctx.Done():
-					return fmt.Errorf("az command %d cancelled: %w", idx+1, ctx.Err())
+					return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
 				case <-time.After(backoff):
 				}
-				out2, runErr2 := runAzCommandStreaming(ctx, args, opts.Writer)
+				out2, runErr2 := runAz()
 				out = out2
 				runErr = runErr2
 				if runErr == nil {
@@ -97,6 +115,9 @@ func ExecuteAzurePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 				}
 			}
 		}
+		if runErr != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if runErr != nil {
 			// If the subscription is valid but this resource provider isn't registered yet,
 			// try registering it (bounded attempts; only when it fails).
@@ -113,7 +134,7 @@ func ExecuteAzurePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 						return fmt.Errorf("azure provider registration failed: %w", regErr)
 					}
 
-					out2, runErr2 := runAzCommandStreaming(ctx, args, opts.Writer)
+					out2, runErr2 := runAz()
 					out = out2
 					runErr = runErr2
 				}
@@ -126,7 +147,7 @@ func ExecuteAzurePlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 			// If Azure CLI loses track of subscription mid-run, try resetting once and retry.
 			if subscriptionID != "" && isAzSubscriptionNotFound(out) {
 				_ = setAzureSubscription(ctx, subscriptionID)
-				out2, runErr2 := runAzCommandStreaming(ctx, args, opts.Writer)
+				out2, runErr2 := runAz()
 				if runErr2 == nil {
 					out = out2
 				} else {
@@ -615,6 +636,7 @@ func runAzCommandStreaming(ctx context.Context, args []string, w io.Writer) (str
 	cmd.Stderr = mw
 
 	err = cmd.Run()
+	audit.RecordStep("az", args, err)
 	out := buf.String()
 	if err != nil {
 		return out, err
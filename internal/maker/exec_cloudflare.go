@@ -8,6 +8,10 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/bgdnvk/clanker/internal/dryrun"
+	"github.com/bgdnvk/clanker/internal/planrun"
+	"github.com/bgdnvk/clanker/internal/redact"
 )
 
 // ExecuteCloudflarePlan executes a Cloudflare infrastructure plan
@@ -33,16 +37,26 @@ func ExecuteCloudflarePlan(ctx context.Context, plan *Plan, opts ExecOptions) er
 		args = append(args, cmdSpec.Args...)
 		args = applyPlanBindings(args, bindings)
 
-		if hasUnresolvedPlaceholders(args) {
+		// A dry run prints values only a skipped command would produce as placeholders
+		if hasUnresolvedPlaceholders(args) && !dryrun.Enabled() {
 			return fmt.Errorf("command %d has unresolved placeholders after substitutions", idx+1)
 		}
 
 		// Determine the tool type from the first argument
 		tool := detectCloudflareTool(args)
 
+		if skipCloudflareCommand(opts.Writer, tool, args) {
+			continue
+		}
+
 		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: %s\n", idx+1, len(plan.Commands), formatCloudflareArgsForLog(tool, args))
 
-		out, runErr := runCloudflareCommand(ctx, tool, args, opts, opts.Writer)
+		out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return runCloudflareCommand(ctx, tool, args, opts, opts.Writer)
+		})
+		if runErr != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if runErr != nil {
 			return fmt.Errorf("cloudflare command %d failed: %w", idx+1, runErr)
 		}
@@ -186,6 +200,31 @@ func runCloudflareAPICommand(ctx context.Context, args []string, opts ExecOption
 		return "", fmt.Errorf("API command requires at least METHOD and ENDPOINT")
 	}
 
+	curlArgs := cloudflareCurlArgs(args, opts.CloudflareAPIToken)
+
+	bin, err := exec.LookPath("curl")
+	if err != nil {
+		return "", fmt.Errorf("curl not found in PATH: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, bin, curlArgs...)
+
+	var buf bytes.Buffer
+	mw := io.MultiWriter(w, &buf)
+	cmd.Stdout = mw
+	cmd.Stderr = mw
+
+	err = cmd.Run()
+	out := buf.String()
+	if err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// cloudflareCurlArgs builds the curl arguments for an API command:
+// METHOD ENDPOINT [BODY]
+func cloudflareCurlArgs(args []string, token string) []string {
 	method := strings.ToUpper(args[0])
 	endpoint := args[1]
 	body := ""
@@ -197,32 +236,30 @@ func runCloudflareAPICommand(ctx context.Context, args []string, opts ExecOption
 		"-s",
 		"-X", method,
 		fmt.Sprintf("https://api.cloudflare.com/client/v4%s", endpoint),
-		"-H", fmt.Sprintf("Authorization: Bearer %s", opts.CloudflareAPIToken),
+		"-H", fmt.Sprintf("Authorization: Bearer %s", token),
 		"-H", "Content-Type: application/json",
 	}
 
 	if body != "" {
 		curlArgs = append(curlArgs, "-d", body)
 	}
+	return curlArgs
+}
 
-	bin, err := exec.LookPath("curl")
-	if err != nil {
-		return "", fmt.Errorf("curl not found in PATH: %w", err)
+// skipCloudflareCommand prints a command that changes resources in place
+// of running it on a dry run. API commands other than GET count as changes.
+func skipCloudflareCommand(w io.Writer, tool string, args []string) bool {
+	if !dryrun.Enabled() {
+		return false
 	}
-
-	cmd := exec.CommandContext(ctx, bin, curlArgs...)
-
-	var buf bytes.Buffer
-	mw := io.MultiWriter(w, &buf)
-	cmd.Stdout = mw
-	cmd.Stderr = mw
-
-	err = cmd.Run()
-	out := buf.String()
-	if err != nil {
-		return out, err
+	if tool != "api" {
+		return dryrun.Skip(w, tool, args[1:])
 	}
-	return out, nil
+	if len(args) < 2 || strings.EqualFold(args[0], "GET") {
+		return false
+	}
+	dryrun.Print(w, "curl", cloudflareCurlArgs(args, redact.Placeholder))
+	return true
 }
 
 // formatCloudflareArgsForLog formats command args for logging
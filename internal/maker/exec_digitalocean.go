@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/bgdnvk/clanker/internal/openclaw"
+	"github.com/bgdnvk/clanker/internal/planrun"
 	"github.com/bgdnvk/clanker/internal/sshknownhosts"
 	"golang.org/x/crypto/ssh"
 )
@@ -365,6 +366,9 @@ func ExecuteDigitalOceanPlan(ctx context.Context, plan *Plan, opts ExecOptions)
 	}
 
 	for idx, cmdSpec := range plan.Commands {
+		if planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if err := validateDoctlCommand(cmdSpec.Args, opts.Destroyer); err != nil {
 			return fmt.Errorf("command %d rejected: %w", idx+1, err)
 		}
@@ -457,7 +461,12 @@ func ExecuteDigitalOceanPlan(ctx context.Context, plan *Plan, opts ExecOptions)
 				}
 			}
 			_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: docker %s\n", idx+1, len(plan.Commands), strings.Join(dockerArgs(args), " "))
-			out, runErr := runDockerCommandStreaming(ctx, args, opts, cloneDir, opts.Writer)
+			out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+				return runDockerCommandStreaming(ctx, args, opts, cloneDir, opts.Writer)
+			})
+			if runErr != nil && planrun.Interrupted(ctx) {
+				return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+			}
 			if runErr != nil && shouldRetryFreshRegistryPush(args, out, registryCreatedThisRun) {
 				out, runErr = retryDOCRPushAfterFreshRegistryCreate(ctx, args, opts, cloneDir, bindings, opts.Writer)
 			}
@@ -495,7 +504,12 @@ func ExecuteDigitalOceanPlan(ctx context.Context, plan *Plan, opts ExecOptions)
 			continue
 		}
 
-		out, runErr := runDoctlCommandWithRetry(ctx, args, opts, opts.Writer)
+		out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return runDoctlCommandWithRetry(ctx, args, opts, opts.Writer)
+		})
+		if runErr != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if runErr != nil {
 			failure := classifyDOFailure(args, out)
 			_, _ = fmt.Fprintf(opts.Writer, "[maker] DO error: category=%s service=%s op=%s\n", failure.Category, failure.Service, failure.Op)
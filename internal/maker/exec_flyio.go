@@ -8,6 +8,8 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/bgdnvk/clanker/internal/planrun"
 )
 
 // ExecuteFlyioPlan executes a Fly.io infrastructure plan by shelling out to
@@ -33,6 +35,9 @@ func ExecuteFlyioPlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 	}
 
 	bindings := make(map[string]string)
+	// Lets `secrets set KEY=<FLY_SECRET_KEY>` take its value from the
+	// environment instead of the plan
+	importSecretLikeEnvVarsIntoBindings(bindings)
 
 	for idx, cmdSpec := range plan.Commands {
 		args := make([]string, 0, len(cmdSpec.Args)+4)
@@ -44,6 +49,7 @@ func ExecuteFlyioPlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 		}
 
 		stdinData := cmdSpec.Stdin
+		lifted := false
 		// Auto-detect `flyctl secrets set` commands that embed values in argv
 		// and lift those values into stdin so they never leak.
 		if stdinData == "" && isFlyioSecretsSetCommand(args) {
@@ -51,18 +57,26 @@ func ExecuteFlyioPlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 			if extracted != "" {
 				stdinData = extracted
 				args = scrubbed
+				lifted = true
 			}
 		} else if stdinData != "" && !strings.HasSuffix(stdinData, "\n") {
 			stdinData += "\n"
 		}
 
-		if hasUnresolvedPlaceholders(args) {
+		// A lifted value that is still a placeholder would be set as the
+		// secret's literal value
+		if hasUnresolvedPlaceholders(args) || (lifted && hasUnresolvedPlaceholders([]string{stdinData})) {
 			return fmt.Errorf("command %d has unresolved placeholders after substitutions", idx+1)
 		}
 
 		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: %s\n", idx+1, len(plan.Commands), formatFlyioArgsForLog(args))
 
-		out, runErr := runFlyioCommandStreamingWithStdin(ctx, args, stdinData, opts, opts.Writer)
+		out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return runFlyioCommandStreamingWithStdin(ctx, args, stdinData, opts, opts.Writer)
+		})
+		if runErr != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if runErr != nil {
 			return fmt.Errorf("flyio command %d failed: %w", idx+1, runErr)
 		}
@@ -1,6 +1,8 @@
 package maker
 
 import (
+	"context"
+	"io"
 	"strings"
 	"testing"
 )
@@ -137,6 +139,16 @@ func TestExtractFlyioSecretValues_NoValues(t *testing.T) {
 	}
 }
 
+func TestExecuteFlyioPlan_RejectsUnresolvedSecretValue(t *testing.T) {
+	plan := &Plan{Provider: "flyio", Commands: []Command{{
+		Args: []string{"flyctl", "secrets", "set", "--app", "myapp", "DATABASE_URL=<FLY_SECRET_DATABASE_URL>"},
+	}}}
+	err := ExecuteFlyioPlan(context.Background(), plan, ExecOptions{FlyioAPIToken: "tok", Writer: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "unresolved placeholders") {
+		t.Fatalf("err = %v, want the unset FLY_SECRET_DATABASE_URL to be rejected", err)
+	}
+}
+
 func TestFormatFlyioArgsForLog_MasksSecretValues(t *testing.T) {
 	args := []string{"flyctl", "secrets", "set", "FOO=supersecret", "BAR=alsosecret", "--app", "x"}
 	log := formatFlyioArgsForLog(args)
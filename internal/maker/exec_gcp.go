@@ -8,7 +8,10 @@ import (
 	"os/exec"
 	"strings"
 
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/dryrun"
 	gcpinfra "github.com/bgdnvk/clanker/internal/gcp"
+	"github.com/bgdnvk/clanker/internal/planrun"
 )
 
 func ExecuteGCPPlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
@@ -20,6 +23,9 @@ func ExecuteGCPPlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 	}
 
 	bindings := make(map[string]string)
+	// Lets plans name values such as <NEW_SECRET_FILE> that are supplied
+	// through the environment rather than written into the plan
+	importSecretLikeEnvVarsIntoBindings(bindings)
 
 	for idx, cmdSpec := range plan.Commands {
 		if err := validateGCloudCommand(cmdSpec.Args, opts.Destroyer); err != nil {
@@ -31,7 +37,8 @@ func ExecuteGCPPlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 		args = applyPlanBindings(args, bindings)
 		args = ensureGCloudJSONFormat(args, cmdSpec.Produces)
 
-		if hasUnresolvedPlaceholders(args) {
+		// A dry run prints values only a skipped command would produce as placeholders
+		if hasUnresolvedPlaceholders(args) && !dryrun.Enabled() {
 			return fmt.Errorf("command %d has unresolved placeholders after substitutions", idx+1)
 		}
 
@@ -42,9 +49,18 @@ func ExecuteGCPPlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 		}
 		gcloudArgs = append(gcloudArgs, "--quiet")
 
+		if dryrun.Skip(opts.Writer, "gcloud", gcloudArgs) {
+			continue
+		}
+
 		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: %s\n", idx+1, len(plan.Commands), formatGCloudArgsForLog(gcloudArgs))
 
-		out, runErr := runGCloudCommandStreaming(ctx, gcloudArgs, opts.Writer)
+		out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return runGCloudCommandStreaming(ctx, gcloudArgs, opts.Writer)
+		})
+		if runErr != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if runErr != nil {
 			return fmt.Errorf("gcloud command %d failed: %w", idx+1, runErr)
 		}
@@ -144,6 +160,7 @@ func runGCloudCommandStreaming(ctx context.Context, args []string, w io.Writer)
 	cmd.Stderr = mw
 
 	err = cmd.Run()
+	audit.RecordStep("gcloud", args, err)
 	out := buf.String()
 	if err != nil {
 		return out, err
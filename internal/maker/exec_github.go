@@ -0,0 +1,166 @@
+package maker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/planrun"
+)
+
+// pendingDeploymentsPathRe matches the one REST endpoint a GitHub plan may
+// call through gh api
+var pendingDeploymentsPathRe = regexp.MustCompile(`^repos/[\w.-]+/[\w.-]+/actions/runs/\d+/pending_deployments$`)
+
+// repoSlugRe matches an owner/name repository
+var repoSlugRe = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+
+// ExecuteGitHubPlan executes a GitHub Actions plan by shelling out to the gh
+// CLI. Plans trigger workflow_dispatch runs, re-run or cancel runs and
+// approve pending deployments; each step must name its repository so the
+// plan never acts on whatever repository the current directory belongs to.
+func ExecuteGitHubPlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
+	if plan == nil {
+		return fmt.Errorf("nil plan")
+	}
+	if opts.Writer == nil {
+		return fmt.Errorf("missing output writer")
+	}
+
+	bindings := make(map[string]string)
+
+	for idx, cmdSpec := range plan.Commands {
+		args := make([]string, 0, len(cmdSpec.Args))
+		args = append(args, cmdSpec.Args...)
+		args = applyPlanBindings(args, bindings)
+
+		if err := validateGitHubCommand(args, opts.Destroyer); err != nil {
+			return fmt.Errorf("command %d rejected after binding: %w", idx+1, err)
+		}
+		if hasUnresolvedPlaceholders(args) {
+			return fmt.Errorf("command %d has unresolved placeholders after substitutions", idx+1)
+		}
+
+		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: %s\n", idx+1, len(plan.Commands), strings.Join(args, " "))
+
+		out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return runGitHubCommandStreaming(ctx, args, opts, opts.Writer)
+		})
+		if runErr != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
+		if runErr != nil {
+			return fmt.Errorf("github command %d failed: %w", idx+1, runErr)
+		}
+
+		learnPlanBindingsFromProduces(cmdSpec.Produces, out, bindings)
+	}
+
+	return nil
+}
+
+// validateGitHubCommand validates that a command is one of the gh
+// invocations a GitHub plan may run: gh workflow run, gh run rerun,
+// gh run cancel, and gh api POST to a run's pending_deployments. Every
+// step must name its repository, and shell operators are rejected.
+func validateGitHubCommand(args []string, allowDestructive bool) error {
+	if len(args) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	if strings.ToLower(strings.TrimSpace(args[0])) != "gh" {
+		return fmt.Errorf("only gh commands are allowed, got: %q", args)
+	}
+	for _, a := range args {
+		if strings.Contains(a, ";") || strings.Contains(a, "|") || strings.Contains(a, "&&") || strings.ContainsAny(a, "\n\r") {
+			return fmt.Errorf("shell operators are not allowed")
+		}
+		if !allowDestructive && strings.EqualFold(a, "--force") {
+			return fmt.Errorf("--force is blocked outside destroyer mode")
+		}
+	}
+	if len(args) < 3 {
+		return fmt.Errorf("incomplete gh command: %q", args)
+	}
+
+	group := strings.ToLower(args[1])
+	verb := strings.ToLower(args[2])
+	switch {
+	case group == "workflow" && verb == "run",
+		group == "run" && (verb == "rerun" || verb == "cancel"):
+		repo := githubRepoFlag(args)
+		if repo == "" {
+			return fmt.Errorf("gh %s %s must name its repository with --repo owner/name", group, verb)
+		}
+		if !repoSlugRe.MatchString(repo) {
+			return fmt.Errorf("invalid repository %q", repo)
+		}
+		return nil
+	case group == "api":
+		method, endpoint := "", ""
+		for i := 2; i < len(args); i++ {
+			switch a := args[i]; {
+			case a == "--method" || a == "-X":
+				if i+1 < len(args) {
+					method = strings.ToUpper(args[i+1])
+					i++
+				}
+			case strings.HasPrefix(a, "--method="):
+				method = strings.ToUpper(strings.TrimPrefix(a, "--method="))
+			case a == "-f" || a == "-F" || a == "--field" || a == "--raw-field" || a == "-H" || a == "--header":
+				i++
+			case strings.HasPrefix(a, "-"):
+				return fmt.Errorf("gh api flag %s is not allowed in plans", a)
+			case endpoint == "":
+				endpoint = strings.TrimPrefix(a, "/")
+			}
+		}
+		if method != "POST" || !pendingDeploymentsPathRe.MatchString(endpoint) {
+			return fmt.Errorf("gh api is limited to POST repos/<owner>/<repo>/actions/runs/<id>/pending_deployments, got: %s %s", method, endpoint)
+		}
+		return nil
+	}
+	return fmt.Errorf("gh %s %s is not allowed in plans", group, verb)
+}
+
+// githubRepoFlag returns the --repo/-R value of a gh command, or ""
+func githubRepoFlag(args []string) string {
+	for i, a := range args {
+		switch {
+		case (a == "--repo" || a == "-R") && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(a, "--repo="):
+			return strings.TrimPrefix(a, "--repo=")
+		}
+	}
+	return ""
+}
+
+// runGitHubCommandStreaming executes gh with streaming output. A configured
+// token is passed as GH_TOKEN; otherwise gh uses its own login.
+func runGitHubCommandStreaming(ctx context.Context, args []string, opts ExecOptions, w io.Writer) (string, error) {
+	bin, err := exec.LookPath("gh")
+	if err != nil {
+		return "", fmt.Errorf("gh not found in PATH (install from https://cli.github.com/)")
+	}
+
+	cmd := exec.CommandContext(ctx, bin, args[1:]...)
+	cmd.Env = os.Environ()
+	if opts.GitHubToken != "" {
+		cmd.Env = append(cmd.Env, "GH_TOKEN="+opts.GitHubToken)
+	}
+	// gh prompts for confirmation on a TTY; plans are already reviewed
+	cmd.Env = append(cmd.Env, "GH_PROMPT_DISABLED=1")
+
+	var buf bytes.Buffer
+	mw := io.MultiWriter(w, &buf)
+	cmd.Stdout = mw
+	cmd.Stderr = mw
+
+	err = cmd.Run()
+	return buf.String(), err
+}
@@ -0,0 +1,53 @@
+package maker
+
+import "testing"
+
+func TestValidateGitHubCommand_AllowsPlanSteps(t *testing.T) {
+	cases := [][]string{
+		{"gh", "workflow", "run", "deploy.yml", "--repo", "acme/shop", "--ref", "main", "-f", "environment=staging"},
+		{"gh", "run", "rerun", "9876543210", "--failed", "--repo", "acme/shop"},
+		{"gh", "run", "cancel", "500", "-R", "acme/shop"},
+		{"gh", "api", "--method", "POST", "repos/acme/shop/actions/runs/700/pending_deployments", "-F", "environment_ids[]=12", "-f", "state=approved", "-f", "comment=ok"},
+	}
+	for _, args := range cases {
+		if err := validateGitHubCommand(args, false); err != nil {
+			t.Errorf("%v should pass, got: %v", args, err)
+		}
+	}
+}
+
+func TestValidateGitHubCommand_Rejects(t *testing.T) {
+	cases := [][]string{
+		{},
+		{"git", "push"},
+		{"gh", "repo", "delete", "acme/shop", "--yes"},
+		{"gh", "secret", "set", "TOKEN", "--repo", "acme/shop"},
+		{"gh", "run", "cancel", "500"},
+		{"gh", "workflow", "run", "deploy.yml", "--repo", "acme"},
+		{"gh", "workflow", "run", "deploy.yml", "--repo", "acme/shop", "-f", "cmd=a;b"},
+		{"gh", "api", "repos/acme/shop/actions/runs/700/pending_deployments"},
+		{"gh", "api", "--method", "DELETE", "repos/acme/shop"},
+		{"gh", "api", "--method", "POST", "repos/acme/shop/actions/runs/700/pending_deployments", "--hostname", "evil.example"},
+	}
+	for _, args := range cases {
+		if err := validateGitHubCommand(args, true); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+func TestValidateGitHubCommand_BlocksForceWithoutDestroyer(t *testing.T) {
+	args := []string{"gh", "run", "cancel", "500", "--repo", "acme/shop", "--force"}
+	if err := validateGitHubCommand(args, false); err == nil {
+		t.Error("--force must be blocked outside destroyer mode")
+	}
+	if err := validateGitHubCommand(args, true); err != nil {
+		t.Errorf("destroyer mode should allow --force, got: %v", err)
+	}
+}
+
+func TestInferProviderFromGHCommands(t *testing.T) {
+	if got := inferProviderFromCommands([]Command{{Args: []string{"gh", "run", "cancel", "1", "--repo", "acme/shop"}}}); got != "github" {
+		t.Errorf("inferProviderFromCommands = %q, want github", got)
+	}
+}
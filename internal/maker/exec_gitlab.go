@@ -0,0 +1,140 @@
+package maker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/planrun"
+)
+
+// gitlabRetryPathRe matches the REST endpoints a GitLab plan may call:
+// retrying a pipeline or a job of a URL-encoded project path
+var gitlabRetryPathRe = regexp.MustCompile(`^projects/[\w.%-]+/(?:pipelines|jobs)/\d+/retry$`)
+
+// gitlabHostRe matches a bare host name, with an optional port
+var gitlabHostRe = regexp.MustCompile(`^[\w.-]+(?::\d+)?$`)
+
+// ExecuteGitLabPlan executes a GitLab plan by shelling out to the glab CLI.
+// Plans retry failed pipelines and jobs; each step names its project in the
+// endpoint and its host with --hostname, so the plan never acts on whatever
+// project the current directory belongs to.
+func ExecuteGitLabPlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
+	if plan == nil {
+		return fmt.Errorf("nil plan")
+	}
+	if opts.Writer == nil {
+		return fmt.Errorf("missing output writer")
+	}
+
+	bindings := make(map[string]string)
+
+	for idx, cmdSpec := range plan.Commands {
+		args := make([]string, 0, len(cmdSpec.Args))
+		args = append(args, cmdSpec.Args...)
+		args = applyPlanBindings(args, bindings)
+
+		if err := validateGitLabCommand(args); err != nil {
+			return fmt.Errorf("command %d rejected after binding: %w", idx+1, err)
+		}
+		if hasUnresolvedPlaceholders(args) {
+			return fmt.Errorf("command %d has unresolved placeholders after substitutions", idx+1)
+		}
+
+		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: %s\n", idx+1, len(plan.Commands), strings.Join(args, " "))
+
+		out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return runGitLabCommandStreaming(ctx, args, opts, opts.Writer)
+		})
+		if runErr != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
+		if runErr != nil {
+			return fmt.Errorf("gitlab command %d failed: %w", idx+1, runErr)
+		}
+
+		learnPlanBindingsFromProduces(cmdSpec.Produces, out, bindings)
+	}
+
+	return nil
+}
+
+// validateGitLabCommand validates that a command is a glab api POST to a
+// pipeline or job retry endpoint with an explicit --hostname. Other flags
+// and shell operators are rejected.
+func validateGitLabCommand(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("incomplete command: %q", args)
+	}
+	if strings.ToLower(strings.TrimSpace(args[0])) != "glab" {
+		return fmt.Errorf("only glab commands are allowed, got: %q", args)
+	}
+	for _, a := range args {
+		if strings.Contains(a, ";") || strings.Contains(a, "|") || strings.Contains(a, "&&") || strings.ContainsAny(a, "\n\r") {
+			return fmt.Errorf("shell operators are not allowed")
+		}
+	}
+	if strings.ToLower(args[1]) != "api" {
+		return fmt.Errorf("glab %s is not allowed in plans", args[1])
+	}
+
+	method, endpoint, host := "", "", ""
+	for i := 2; i < len(args); i++ {
+		switch a := args[i]; {
+		case (a == "--method" || a == "-X") && i+1 < len(args):
+			method = strings.ToUpper(args[i+1])
+			i++
+		case strings.HasPrefix(a, "--method="):
+			method = strings.ToUpper(strings.TrimPrefix(a, "--method="))
+		case a == "--hostname" && i+1 < len(args):
+			host = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--hostname="):
+			host = strings.TrimPrefix(a, "--hostname=")
+		case strings.HasPrefix(a, "-"):
+			return fmt.Errorf("glab api flag %s is not allowed in plans", a)
+		case endpoint == "":
+			endpoint = strings.TrimPrefix(a, "/")
+		default:
+			return fmt.Errorf("unexpected glab api argument %q", a)
+		}
+	}
+	if method != "POST" || !gitlabRetryPathRe.MatchString(endpoint) {
+		return fmt.Errorf("glab api is limited to POST projects/<project>/(pipelines|jobs)/<id>/retry, got: %s %s", method, endpoint)
+	}
+	if !gitlabHostRe.MatchString(host) {
+		return fmt.Errorf("glab api must name its host with --hostname, got %q", host)
+	}
+	return nil
+}
+
+// runGitLabCommandStreaming executes glab with streaming output. A
+// configured token is passed as GITLAB_TOKEN; otherwise glab uses its own
+// login.
+func runGitLabCommandStreaming(ctx context.Context, args []string, opts ExecOptions, w io.Writer) (string, error) {
+	bin, err := exec.LookPath("glab")
+	if err != nil {
+		return "", fmt.Errorf("glab not found in PATH (install from https://gitlab.com/gitlab-org/cli)")
+	}
+
+	cmd := exec.CommandContext(ctx, bin, args[1:]...)
+	cmd.Env = os.Environ()
+	if opts.GitLabToken != "" {
+		cmd.Env = append(cmd.Env, "GITLAB_TOKEN="+opts.GitLabToken)
+	}
+	// glab prompts on a TTY; plans are already reviewed
+	cmd.Env = append(cmd.Env, "NO_PROMPT=1")
+
+	var buf bytes.Buffer
+	mw := io.MultiWriter(w, &buf)
+	cmd.Stdout = mw
+	cmd.Stderr = mw
+
+	err = cmd.Run()
+	return buf.String(), err
+}
@@ -0,0 +1,39 @@
+package maker
+
+import "testing"
+
+func TestValidateGitLabCommand_AllowsRetries(t *testing.T) {
+	cases := [][]string{
+		{"glab", "api", "--method", "POST", "projects/acme%2Fshop/pipelines/123456/retry", "--hostname", "gitlab.com"},
+		{"glab", "api", "-X", "POST", "projects/acme%2Fplatform%2Fapi/jobs/98765/retry", "--hostname=gitlab.acme.dev:8443"},
+	}
+	for _, args := range cases {
+		if err := validateGitLabCommand(args); err != nil {
+			t.Errorf("%v should pass, got: %v", args, err)
+		}
+	}
+}
+
+func TestValidateGitLabCommand_Rejects(t *testing.T) {
+	cases := [][]string{
+		{},
+		{"gh", "api", "--method", "POST", "projects/acme%2Fshop/pipelines/1/retry", "--hostname", "gitlab.com"},
+		{"glab", "ci", "retry", "98765"},
+		{"glab", "api", "projects/acme%2Fshop/pipelines/1/retry", "--hostname", "gitlab.com"},
+		{"glab", "api", "--method", "POST", "projects/acme%2Fshop/pipelines/1/retry"},
+		{"glab", "api", "--method", "DELETE", "projects/acme%2Fshop", "--hostname", "gitlab.com"},
+		{"glab", "api", "--method", "POST", "projects/acme%2Fshop/pipelines/1/retry", "--hostname", "gitlab.com", "-f", "x=1"},
+		{"glab", "api", "--method", "POST", "projects/acme%2Fshop/pipelines/1/retry", "--hostname", "https://evil.example/x"},
+	}
+	for _, args := range cases {
+		if err := validateGitLabCommand(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+func TestInferProviderFromGlabCommands(t *testing.T) {
+	if got := inferProviderFromCommands([]Command{{Args: []string{"glab", "api", "--method", "POST", "projects/a%2Fb/jobs/1/retry"}}}); got != "gitlab" {
+		t.Errorf("inferProviderFromCommands = %q, want gitlab", got)
+	}
+}
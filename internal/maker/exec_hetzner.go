@@ -8,6 +8,8 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/bgdnvk/clanker/internal/planrun"
 )
 
 // ExecuteHetznerPlan executes a Hetzner Cloud infrastructure plan
@@ -39,7 +41,12 @@ func ExecuteHetznerPlan(ctx context.Context, plan *Plan, opts ExecOptions) error
 
 		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: hcloud %s\n", idx+1, len(plan.Commands), strings.Join(args[1:], " "))
 
-		out, runErr := runHcloudCommandStreaming(ctx, args, opts, opts.Writer)
+		out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return runHcloudCommandStreaming(ctx, args, opts, opts.Writer)
+		})
+		if runErr != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if runErr != nil {
 			return fmt.Errorf("hetzner command %d failed: %w", idx+1, runErr)
 		}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/bgdnvk/clanker/internal/oracle"
+	"github.com/bgdnvk/clanker/internal/planrun"
 )
 
 // ExecuteOraclePlan executes an Oracle Cloud Infrastructure plan through the OCI CLI.
@@ -50,7 +51,12 @@ func ExecuteOraclePlan(ctx context.Context, plan *Plan, opts ExecOptions) error
 		}
 
 		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: oci %s\n", idx+1, len(plan.Commands), strings.Join(args[1:], " "))
-		out, runErr := runOCICommandStreaming(ctx, client, args[1:], opts.Writer)
+		out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return runOCICommandStreaming(ctx, client, args[1:], opts.Writer)
+		})
+		if runErr != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if runErr != nil {
 			return fmt.Errorf("oracle command %d failed: %w", idx+1, runErr)
 		}
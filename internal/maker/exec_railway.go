@@ -8,6 +8,8 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/bgdnvk/clanker/internal/planrun"
 )
 
 // ExecuteRailwayPlan executes a Railway infrastructure plan by shelling out
@@ -24,6 +26,9 @@ func ExecuteRailwayPlan(ctx context.Context, plan *Plan, opts ExecOptions) error
 	}
 
 	bindings := make(map[string]string)
+	// Lets `variable set KEY=<RAILWAY_SECRET_KEY>` take its value from the
+	// environment instead of the plan
+	importSecretLikeEnvVarsIntoBindings(bindings)
 
 	for idx, cmdSpec := range plan.Commands {
 		args := make([]string, 0, len(cmdSpec.Args)+4)
@@ -45,7 +50,12 @@ func ExecuteRailwayPlan(ctx context.Context, plan *Plan, opts ExecOptions) error
 
 		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: %s\n", idx+1, len(plan.Commands), formatRailwayArgsForLog(args))
 
-		out, runErr := runRailwayCommandStreamingWithStdin(ctx, args, stdinData, opts, opts.Writer)
+		out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return runRailwayCommandStreamingWithStdin(ctx, args, stdinData, opts, opts.Writer)
+		})
+		if runErr != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if runErr != nil {
 			return fmt.Errorf("railway command %d failed: %w", idx+1, runErr)
 		}
@@ -0,0 +1,102 @@
+package maker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/planrun"
+	"github.com/bgdnvk/clanker/internal/sentry"
+)
+
+// sentryIssuesPathRe is the only endpoint Sentry plans may call: the bulk
+// issue update, scoped to numeric issue IDs
+var sentryIssuesPathRe = regexp.MustCompile(`^/organizations/[A-Za-z0-9_-]+/issues/\?id=\d+(?:&id=\d+)*$`)
+
+// ExecuteSentryPlan executes a Sentry triage plan. Commands take the form
+// ["sentry-api", "PUT", "/organizations/{org}/issues/?id=N", BODY] and are
+// sent through sentry.Client, which owns auth, retries and error decoding.
+// Only issue status and assignment changes are accepted, so a plan cannot
+// reach project settings, alert rules or anything else the token can touch.
+func ExecuteSentryPlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
+	if plan == nil {
+		return fmt.Errorf("nil plan")
+	}
+	if opts.Writer == nil {
+		return fmt.Errorf("missing output writer")
+	}
+	if opts.SentryAuthToken == "" {
+		return fmt.Errorf("missing sentry auth token (set sentry.auth_token in ~/.clanker.yaml or export SENTRY_AUTH_TOKEN)")
+	}
+
+	client, err := sentry.NewClient(opts.SentryAuthToken, opts.SentryOrgSlug, opts.SentryHost, opts.Debug)
+	if err != nil {
+		return fmt.Errorf("build sentry client: %w", err)
+	}
+
+	for idx, cmdSpec := range plan.Commands {
+		args := cmdSpec.Args
+		if err := validateSentryCommand(args); err != nil {
+			return fmt.Errorf("command %d rejected: %w", idx+1, err)
+		}
+
+		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: sentry-api PUT %s %s\n",
+			idx+1, len(plan.Commands), args[2], args[3])
+		_, err := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			_, _, err := client.Do(ctx, "PUT", args[2], json.RawMessage(args[3]))
+			return "", err
+		})
+		if err != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
+		if err != nil {
+			return fmt.Errorf("sentry command %d failed (PUT %s): %w", idx+1, args[2], err)
+		}
+	}
+
+	return nil
+}
+
+// validateSentryCommand accepts only [sentry-api, PUT, issues path, body]
+// where the body sets an issue's status, its assignee, or both
+func validateSentryCommand(args []string) error {
+	if len(args) != 4 {
+		return fmt.Errorf("sentry plan commands take exactly 4 args [verb, method, path, body], got %d", len(args))
+	}
+	if strings.ToLower(strings.TrimSpace(args[0])) != "sentry-api" {
+		return fmt.Errorf("only the sentry-api verb is supported (got %q)", args[0])
+	}
+	if strings.ToUpper(strings.TrimSpace(args[1])) != "PUT" {
+		return fmt.Errorf("sentry plans may only update issues with PUT, got %q", args[1])
+	}
+	if !sentryIssuesPathRe.MatchString(args[2]) {
+		return fmt.Errorf("sentry plans may only call /organizations/{org}/issues/?id=N, got %q", args[2])
+	}
+
+	var body map[string]any
+	if err := json.Unmarshal([]byte(args[3]), &body); err != nil {
+		return fmt.Errorf("sentry body is not a JSON object: %w", err)
+	}
+	if len(body) == 0 {
+		return fmt.Errorf("sentry body changes nothing")
+	}
+	for key, value := range body {
+		switch key {
+		case "status":
+			switch sentry.IssueStatus(fmt.Sprint(value)) {
+			case sentry.IssueStatusResolved, sentry.IssueStatusIgnored, sentry.IssueStatusUnresolved:
+			default:
+				return fmt.Errorf("unsupported issue status %v", value)
+			}
+		case "assignedTo":
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("assignedTo must be a username or email")
+			}
+		default:
+			return fmt.Errorf("sentry plans may only change status or assignedTo, got %q", key)
+		}
+	}
+	return nil
+}
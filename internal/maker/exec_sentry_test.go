@@ -0,0 +1,47 @@
+package maker
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateSentryCommand(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		ok   bool
+	}{
+		{"resolve", []string{"sentry-api", "PUT", "/organizations/acme/issues/?id=4012345", `{"status":"resolved"}`}, true},
+		{"assign several", []string{"sentry-api", "PUT", "/organizations/acme/issues/?id=1&id=2", `{"assignedTo":"jane@example.com"}`}, true},
+		{"missing body", []string{"sentry-api", "PUT", "/organizations/acme/issues/?id=1"}, false},
+		{"wrong verb", []string{"sentry", "PUT", "/organizations/acme/issues/?id=1", `{"status":"resolved"}`}, false},
+		{"delete", []string{"sentry-api", "DELETE", "/organizations/acme/issues/?id=1", `{"status":"resolved"}`}, false},
+		{"bulk without ids", []string{"sentry-api", "PUT", "/organizations/acme/issues/", `{"status":"resolved"}`}, false},
+		{"injected query", []string{"sentry-api", "PUT", "/organizations/acme/issues/?id=1&query=is:unresolved", `{"status":"resolved"}`}, false},
+		{"other endpoint", []string{"sentry-api", "PUT", "/projects/acme/backend/", `{"status":"resolved"}`}, false},
+		{"unknown status", []string{"sentry-api", "PUT", "/organizations/acme/issues/?id=1", `{"status":"deleted"}`}, false},
+		{"extra field", []string{"sentry-api", "PUT", "/organizations/acme/issues/?id=1", `{"status":"resolved","isPublic":true}`}, false},
+		{"empty body", []string{"sentry-api", "PUT", "/organizations/acme/issues/?id=1", `{}`}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateSentryCommand(tc.args)
+			if tc.ok && err != nil {
+				t.Errorf("expected ok, got: %v", err)
+			}
+			if !tc.ok && err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestExecuteSentryPlan_RequiresToken(t *testing.T) {
+	plan := &Plan{Provider: "sentry", Commands: []Command{{Args: []string{"sentry-api", "PUT", "/organizations/acme/issues/?id=1", `{"status":"resolved"}`}}}}
+	err := ExecuteSentryPlan(context.Background(), plan, ExecOptions{Writer: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "auth token") {
+		t.Fatalf("expected missing token error, got %v", err)
+	}
+}
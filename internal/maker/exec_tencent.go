@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/bgdnvk/clanker/internal/planrun"
 	"github.com/bgdnvk/clanker/internal/tencent"
 )
 
@@ -42,6 +43,11 @@ func ExecuteTencentPlan(ctx context.Context, plan *Plan, opts ExecOptions) error
 	priorOutputs := make([]string, 0, len(plan.Commands))
 
 	for idx, cmdSpec := range plan.Commands {
+		// The Tencent client takes no context, so Ctrl-C stops the plan
+		// between commands and step timeouts do not apply
+		if planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		args := make([]string, 0, len(cmdSpec.Args))
 		args = append(args, cmdSpec.Args...)
 		args = applyPlanBindings(args, bindings)
@@ -8,6 +8,8 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/bgdnvk/clanker/internal/planrun"
 )
 
 // ExecuteVercelPlan executes a Vercel infrastructure plan by shelling out to
@@ -24,6 +26,9 @@ func ExecuteVercelPlan(ctx context.Context, plan *Plan, opts ExecOptions) error
 	}
 
 	bindings := make(map[string]string)
+	// Lets `env add KEY <VERCEL_SECRET_KEY>` take its value from the
+	// environment instead of the plan
+	importSecretLikeEnvVarsIntoBindings(bindings)
 
 	for idx, cmdSpec := range plan.Commands {
 		args := make([]string, 0, len(cmdSpec.Args)+4)
@@ -35,6 +40,7 @@ func ExecuteVercelPlan(ctx context.Context, plan *Plan, opts ExecOptions) error
 		}
 
 		stdinData := cmdSpec.Stdin
+		lifted := false
 		// Auto-detect env add commands that need stdin piping even
 		// when the plan was generated without the stdin field.
 		if stdinData == "" && isVercelEnvAddCommand(args) && len(args) >= 5 {
@@ -42,17 +48,25 @@ func ExecuteVercelPlan(ctx context.Context, plan *Plan, opts ExecOptions) error
 			// Extract the value and rewrite args to remove it.
 			stdinData = args[4] + "\n"
 			args = append(args[:4], args[5:]...)
+			lifted = true
 		} else if stdinData != "" && !strings.HasSuffix(stdinData, "\n") {
 			stdinData += "\n"
 		}
 
-		if hasUnresolvedPlaceholders(args) {
+		// A lifted value that is still a placeholder would be stored as the
+		// variable's literal value
+		if hasUnresolvedPlaceholders(args) || (lifted && hasUnresolvedPlaceholders([]string{stdinData})) {
 			return fmt.Errorf("command %d has unresolved placeholders after substitutions", idx+1)
 		}
 
 		_, _ = fmt.Fprintf(opts.Writer, "[maker] running %d/%d: %s\n", idx+1, len(plan.Commands), formatVercelArgsForLog(args))
 
-		out, runErr := runVercelCommandStreamingWithStdin(ctx, args, stdinData, opts, opts.Writer)
+		out, runErr := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return runVercelCommandStreamingWithStdin(ctx, args, stdinData, opts, opts.Writer)
+		})
+		if runErr != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if runErr != nil {
 			return fmt.Errorf("vercel command %d failed: %w", idx+1, runErr)
 		}
@@ -0,0 +1,18 @@
+package maker
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestExecuteVercelPlan_RejectsUnresolvedEnvValue(t *testing.T) {
+	plan := &Plan{Provider: "vercel", Commands: []Command{{
+		Args: []string{"vercel", "env", "add", "DATABASE_URL", "<VERCEL_SECRET_DATABASE_URL>", "production", "--project", "site", "--force"},
+	}}}
+	err := ExecuteVercelPlan(context.Background(), plan, ExecOptions{VercelAPIToken: "tok", Writer: io.Discard})
+	if err == nil || !strings.Contains(err.Error(), "unresolved placeholders") {
+		t.Fatalf("err = %v, want the unset VERCEL_SECRET_DATABASE_URL to be rejected", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/bgdnvk/clanker/internal/planrun"
 	"github.com/bgdnvk/clanker/internal/verda"
 )
 
@@ -61,7 +62,12 @@ func ExecuteVerdaPlan(ctx context.Context, plan *Plan, opts ExecOptions) error {
 			_, _ = fmt.Fprintf(opts.Writer, "[maker]   body: %s\n", body)
 		}
 
-		out, err := client.RunAPIWithContext(ctx, method, path, body)
+		out, err := runPlanCommand(ctx, cmdSpec, func(ctx context.Context) (string, error) {
+			return client.RunAPIWithContext(ctx, method, path, body)
+		})
+		if err != nil && planrun.Interrupted(ctx) {
+			return planInterrupted(opts.Writer, idx, len(plan.Commands), false)
+		}
 		if err != nil {
 			return fmt.Errorf("verda command %d failed (%s %s): %w", idx+1, method, path, err)
 		}
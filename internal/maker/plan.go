@@ -6,6 +6,8 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/bgdnvk/clanker/internal/planrun"
 )
 
 var dollarPlaceholderRe = regexp.MustCompile(`\$\{([A-Z0-9_]+)\}`)
@@ -41,6 +43,9 @@ type Command struct {
 	// Stdin is optional data piped to the command's standard input.
 	// Used by commands like `vercel env add` that read values from stdin.
 	Stdin string `json:"stdin,omitempty"`
+	// Timeout stops the command when it runs longer, e.g. "10m". Empty uses
+	// --step-timeout.
+	Timeout string `json:"timeout,omitempty"`
 }
 
 func ParsePlan(raw string) (*Plan, error) {
@@ -88,6 +93,9 @@ func ParsePlan(raw string) (*Plan, error) {
 		if len(p.Commands[i].Args) == 0 {
 			return nil, fmt.Errorf("command %d has empty args", i)
 		}
+		if _, err := planrun.ParseTimeout(p.Commands[i].Timeout); err != nil {
+			return nil, fmt.Errorf("command %d: %w", i, err)
+		}
 		if len(p.Commands[i].Produces) == 0 {
 			p.Commands[i].Produces = nil
 		}
@@ -176,6 +184,10 @@ func inferProviderFromCommands(cmds []Command) string {
 			return "gcp"
 		case "az":
 			return "azure"
+		case "gh":
+			return "github"
+		case "glab":
+			return "gitlab"
 		}
 	}
 	return "aws"
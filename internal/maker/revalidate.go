@@ -0,0 +1,134 @@
+package maker
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// awsResourceIDPattern matches EC2-style resource IDs written into a plan
+// when it was generated. Values bound at apply time are placeholders and
+// never match.
+var awsResourceIDPattern = regexp.MustCompile(`\b(vpc|subnet|sg|igw|rtb|nat|eipalloc|i|ami|vol|snap|lt|eni|acl)-(?:[0-9a-f]{17}|[0-9a-f]{8})\b`)
+
+// awsIDLookups resolves each ID prefix with a describe call whose --query
+// prints the resource's state, or its ID when it has no state
+var awsIDLookups = map[string][]string{
+	"vpc":      {"ec2", "describe-vpcs", "--vpc-ids", "", "--query", "Vpcs[0].State"},
+	"subnet":   {"ec2", "describe-subnets", "--subnet-ids", "", "--query", "Subnets[0].State"},
+	"sg":       {"ec2", "describe-security-groups", "--group-ids", "", "--query", "SecurityGroups[0].GroupId"},
+	"igw":      {"ec2", "describe-internet-gateways", "--internet-gateway-ids", "", "--query", "InternetGateways[0].InternetGatewayId"},
+	"rtb":      {"ec2", "describe-route-tables", "--route-table-ids", "", "--query", "RouteTables[0].RouteTableId"},
+	"nat":      {"ec2", "describe-nat-gateways", "--nat-gateway-ids", "", "--query", "NatGateways[0].State"},
+	"eipalloc": {"ec2", "describe-addresses", "--allocation-ids", "", "--query", "Addresses[0].AllocationId"},
+	"i":        {"ec2", "describe-instances", "--instance-ids", "", "--query", "Reservations[0].Instances[0].State.Name"},
+	"ami":      {"ec2", "describe-images", "--image-ids", "", "--query", "Images[0].State"},
+	"vol":      {"ec2", "describe-volumes", "--volume-ids", "", "--query", "Volumes[0].State"},
+	"snap":     {"ec2", "describe-snapshots", "--snapshot-ids", "", "--query", "Snapshots[0].State"},
+	"lt":       {"ec2", "describe-launch-templates", "--launch-template-ids", "", "--query", "LaunchTemplates[0].LaunchTemplateId"},
+	"eni":      {"ec2", "describe-network-interfaces", "--network-interface-ids", "", "--query", "NetworkInterfaces[0].Status"},
+	"acl":      {"ec2", "describe-network-acls", "--network-acl-ids", "", "--query", "NetworkAcls[0].NetworkAclId"},
+}
+
+// goneStates are resource states that mean the resource is no longer usable
+var goneStates = map[string]bool{
+	"none": true, "terminated": true, "shutting-down": true, "deleted": true,
+	"deleting": true, "deregistered": true, "failed": true,
+}
+
+// ResourceDrift is a resource a plan references that changed since the plan
+// was generated
+type ResourceDrift struct {
+	ID      string `json:"id"`
+	Steps   []int  `json:"steps"` // 1-based plan steps that reference the resource
+	Problem string `json:"problem"`
+}
+
+func (d ResourceDrift) String() string {
+	steps := make([]string, 0, len(d.Steps))
+	for _, s := range d.Steps {
+		steps = append(steps, fmt.Sprint(s))
+	}
+	return fmt.Sprintf("%s (step %s): %s", d.ID, strings.Join(steps, ", "), d.Problem)
+}
+
+// AWSLookup runs a read-only aws CLI command and returns its text output
+type AWSLookup func(ctx context.Context, args []string) (string, error)
+
+// ReferencedAWSResources maps each literal EC2 resource ID in the plan to the
+// 1-based steps that reference it
+func ReferencedAWSResources(plan *Plan) map[string][]int {
+	refs := make(map[string][]int)
+	for i, cmd := range plan.Commands {
+		seen := make(map[string]bool)
+		for _, arg := range cmd.Args {
+			for _, id := range awsResourceIDPattern.FindAllString(arg, -1) {
+				if !seen[id] {
+					seen[id] = true
+					refs[id] = append(refs[id], i+1)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// RevalidateAWSPlan re-resolves the EC2 resources an AWS plan references and
+// reports the ones that were deleted since it was generated. Resources that
+// could not be checked are returned as warnings. A nil lookup runs the aws
+// CLI with the profile and region in opts.
+func RevalidateAWSPlan(ctx context.Context, plan *Plan, opts ExecOptions, lookup AWSLookup) ([]ResourceDrift, []string) {
+	if lookup == nil {
+		lookup = awsCLILookup(opts)
+	}
+	refs := ReferencedAWSResources(plan)
+	ids := make([]string, 0, len(refs))
+	for id := range refs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var drifts []ResourceDrift
+	var warnings []string
+	for _, id := range ids {
+		prefix, _, _ := strings.Cut(id, "-")
+		args := append([]string(nil), awsIDLookups[prefix]...)
+		args[3] = id
+		out, err := lookup(ctx, args)
+		state := strings.ToLower(strings.TrimSpace(out))
+		switch {
+		case err != nil && isAWSNotFound(out):
+			drifts = append(drifts, ResourceDrift{ID: id, Steps: refs[id], Problem: "no longer exists"})
+		case err != nil:
+			warnings = append(warnings, fmt.Sprintf("could not re-check %s: %v", id, err))
+		case state == "" || goneStates[state]:
+			problem := "no longer exists"
+			if state != "" && state != "none" {
+				problem = "is " + state
+			}
+			drifts = append(drifts, ResourceDrift{ID: id, Steps: refs[id], Problem: problem})
+		}
+	}
+	return drifts, warnings
+}
+
+func isAWSNotFound(output string) bool {
+	return strings.Contains(output, "NotFound") || strings.Contains(output, ".Unavailable")
+}
+
+func awsCLILookup(opts ExecOptions) AWSLookup {
+	return func(ctx context.Context, args []string) (string, error) {
+		args = append(append([]string(nil), args...), "--output", "text", "--no-cli-pager")
+		if opts.Profile != "" {
+			args = append(args, "--profile", opts.Profile)
+		}
+		if opts.Region != "" {
+			args = append(args, "--region", opts.Region)
+		}
+		out, err := exec.CommandContext(ctx, "aws", args...).CombinedOutput()
+		return string(out), err
+	}
+}
@@ -0,0 +1,65 @@
+package maker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReferencedAWSResources(t *testing.T) {
+	plan := &Plan{Commands: []Command{
+		{Args: []string{"ec2", "create-subnet", "--vpc-id", "vpc-0123456789abcdef0", "--cidr-block", "10.0.1.0/24"}},
+		{Args: []string{"ec2", "run-instances", "--image-id", "ami-12345678", "--subnet-id", "<SUBNET_ID>", "--security-group-ids", "sg-0aaaaaaaaaaaaaaaa"}},
+		{Args: []string{"ec2", "create-tags", "--resources", "vpc-0123456789abcdef0", "--tags", "Key=Name,Value=multi-12345678"}},
+	}}
+	refs := ReferencedAWSResources(plan)
+	if len(refs) != 3 {
+		t.Fatalf("refs = %v", refs)
+	}
+	if got := refs["vpc-0123456789abcdef0"]; len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Errorf("vpc steps = %v", got)
+	}
+	if got := refs["ami-12345678"]; len(got) != 1 || got[0] != 2 {
+		t.Errorf("ami steps = %v", got)
+	}
+}
+
+func TestRevalidateAWSPlan(t *testing.T) {
+	plan := &Plan{Commands: []Command{
+		{Args: []string{"ec2", "create-subnet", "--vpc-id", "vpc-0123456789abcdef0"}},
+		{Args: []string{"ec2", "run-instances", "--image-id", "ami-12345678", "--security-group-ids", "sg-0aaaaaaaaaaaaaaaa"}},
+		{Args: []string{"ec2", "attach-volume", "--instance-id", "i-0bbbbbbbbbbbbbbbb", "--volume-id", "vol-0cccccccccccccccc"}},
+	}}
+	var calls []string
+	lookup := func(ctx context.Context, args []string) (string, error) {
+		calls = append(calls, strings.Join(args[:4], " "))
+		switch args[3] {
+		case "vpc-0123456789abcdef0":
+			return "available\n", nil
+		case "ami-12345678":
+			return "None\n", nil
+		case "sg-0aaaaaaaaaaaaaaaa":
+			return "An error occurred (InvalidGroup.NotFound) when calling the DescribeSecurityGroups operation", errors.New("exit status 254")
+		case "i-0bbbbbbbbbbbbbbbb":
+			return "terminated\n", nil
+		}
+		return "could not connect to the endpoint URL", errors.New("exit status 255")
+	}
+
+	drifts, warnings := RevalidateAWSPlan(context.Background(), plan, ExecOptions{}, lookup)
+	var got []string
+	for _, d := range drifts {
+		got = append(got, d.String())
+	}
+	want := "ami-12345678 (step 2): no longer exists|i-0bbbbbbbbbbbbbbbb (step 3): is terminated|sg-0aaaaaaaaaaaaaaaa (step 2): no longer exists"
+	if strings.Join(got, "|") != want {
+		t.Errorf("drifts = %v", got)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "vol-") {
+		t.Errorf("warnings = %v", warnings)
+	}
+	if len(calls) != 5 || calls[0] != "ec2 describe-images --image-ids ami-12345678" {
+		t.Errorf("calls = %v", calls)
+	}
+}
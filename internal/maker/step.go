@@ -0,0 +1,32 @@
+package maker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/bgdnvk/clanker/internal/planrun"
+)
+
+// runPlanCommand runs one attempt of a plan command under the command's
+// timeout, or --step-timeout when it sets none
+func runPlanCommand(ctx context.Context, cmd Command, run func(context.Context) (string, error)) (string, error) {
+	stepCtx, done, err := planrun.Step(ctx, cmd.Timeout)
+	if err != nil {
+		return "", err
+	}
+	out, err := run(stepCtx)
+	return out, done(err)
+}
+
+// planInterrupted is the error an executor returns when Ctrl-C stops it
+// at command idx. Only the AWS executor keeps a durable checkpoint to
+// resume from.
+func planInterrupted(w io.Writer, idx, total int, checkpointed bool) error {
+	_, _ = fmt.Fprintf(w, "[maker] interrupted at command %d/%d\n", idx+1, total)
+	err := planrun.Interrupt(idx, total)
+	if checkpointed {
+		err.Resume = fmt.Sprintf("run the same apply again to resume from command %d; the commands before it are saved in the checkpoint", idx+1)
+	}
+	return err
+}
@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/audit"
+	"github.com/bgdnvk/clanker/internal/k8s/cluster"
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/bgdnvk/clanker/internal/maker"
+	"github.com/bgdnvk/clanker/internal/redact"
+)
+
+// maxListed is how many commands, findings or issues a message lists
+// before counting the rest
+const maxListed = 8
+
+// PlanApplied describes an applied plan: its summary and the commands it
+// ran, with secret flag values and credentials redacted. A failed apply is
+// an apply_failed message carrying the error.
+func PlanApplied(source string, planJSON []byte, applyErr error) Message {
+	var plan maker.Plan
+	_ = json.Unmarshal(planJSON, &plan)
+
+	msg := Message{Event: EventPlanApplied, Level: LevelSuccess, Title: "Plan applied"}
+	if applyErr != nil {
+		msg.Event, msg.Level, msg.Title = EventApplyFailed, LevelError, "Plan apply failed"
+	}
+	if summary := strings.TrimSpace(plan.Summary); summary != "" {
+		msg.Title += ": " + oneLine(summary)
+	}
+
+	var sb strings.Builder
+	if applyErr != nil {
+		fmt.Fprintf(&sb, "Error: `%s`\n\n", oneLine(redact.Apply(applyErr.Error())))
+	}
+	for i, c := range plan.Commands {
+		if i == maxListed {
+			fmt.Fprintf(&sb, "- ...and %d more commands\n", len(plan.Commands)-maxListed)
+			break
+		}
+		fmt.Fprintf(&sb, "- `%s`\n", redact.Apply(strings.Join(audit.RedactArgs(c.Args), " ")))
+	}
+	msg.Text = strings.TrimSpace(sb.String())
+
+	msg.Fields = append(msg.Fields, Field{Name: "Source", Value: "clanker " + source, Short: true})
+	if plan.Provider != "" {
+		msg.Fields = append(msg.Fields, Field{Name: "Provider", Value: plan.Provider, Short: true})
+	}
+	if len(plan.Commands) > 0 {
+		msg.Fields = append(msg.Fields, Field{Name: "Commands", Value: fmt.Sprint(len(plan.Commands)), Short: true})
+	}
+	return msg
+}
+
+// Alert is a scheduled check changing status
+type Alert struct {
+	Check string
+	Type  string
+	// From is empty the first time the check runs
+	From    string
+	To      string
+	Summary string
+	Details []string
+}
+
+// WatchAlert describes a check changing status. Critical and error alerts
+// are red, warnings amber and recoveries green.
+func WatchAlert(a Alert) Message {
+	from := a.From
+	if from == "" {
+		from = "new"
+	}
+	msg := Message{
+		Event: EventWatchAlert,
+		Level: LevelWarning,
+		Title: fmt.Sprintf("%s is %s (was %s)", a.Check, strings.ToUpper(a.To), from),
+		Fields: []Field{
+			{Name: "Check", Value: a.Type, Short: true},
+		},
+	}
+	switch a.To {
+	case "ok":
+		msg.Level = LevelSuccess
+	case "critical", "error":
+		msg.Level = LevelError
+	}
+	var sb strings.Builder
+	sb.WriteString(a.Summary)
+	if len(a.Details) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(bullets(a.Details))
+	}
+	msg.Text = strings.TrimSpace(sb.String())
+	return msg
+}
+
+// ClusterCreated describes a newly created cluster and how to reach it
+func ClusterCreated(provider string, info *cluster.ClusterInfo, kubeconfig string) Message {
+	msg := Message{Event: EventClusterCreated, Level: LevelSuccess, Title: fmt.Sprintf("%s cluster %s created", provider, info.Name)}
+	for _, f := range []Field{
+		{Name: "Status", Value: info.Status, Short: true},
+		{Name: "Version", Value: info.KubernetesVersion, Short: true},
+		{Name: "Region", Value: info.Region, Short: true},
+		{Name: "Nodes", Value: nodeCount(info), Short: true},
+		{Name: "Endpoint", Value: info.Endpoint},
+		{Name: "Kubeconfig", Value: kubeconfig},
+	} {
+		if f.Value != "" {
+			msg.Fields = append(msg.Fields, f)
+		}
+	}
+	return msg
+}
+
+func nodeCount(info *cluster.ClusterInfo) string {
+	cp, workers := len(info.ControlPlaneNodes), len(info.WorkerNodes)
+	if cp+workers == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d control plane, %d workers", cp, workers)
+}
+
+// Diagnosis summarises an SRE diagnostic report: its summary, the likely
+// root causes and the most severe issues. A report with critical issues
+// is an error message.
+func Diagnosis(event Event, report *sre.DiagnosticReport) Message {
+	scope := report.Scope
+	switch {
+	case report.ResourceName != "":
+		scope = fmt.Sprintf("%s %s", report.ResourceType, qualified(report.Namespace, report.ResourceName))
+	case report.Namespace != "":
+		scope = "namespace " + report.Namespace
+	}
+	msg := Message{Event: event, Level: LevelSuccess, Title: fmt.Sprintf("Diagnosis of %s", scope)}
+
+	var critical, warning []string
+	for _, issue := range report.Issues {
+		line := fmt.Sprintf("%s/%s: %s", issue.ResourceType, qualified(issue.Namespace, issue.ResourceName), issue.Message)
+		switch issue.Severity {
+		case sre.SeverityCritical:
+			critical = append(critical, line)
+		case sre.SeverityWarning:
+			warning = append(warning, line)
+		}
+	}
+	switch {
+	case len(critical) > 0:
+		msg.Level = LevelError
+	case len(warning) > 0:
+		msg.Level = LevelWarning
+	}
+
+	var sb strings.Builder
+	sb.WriteString(report.Summary)
+	if len(report.RootCauses) > 0 {
+		var causes []string
+		for _, rc := range report.RootCauses {
+			causes = append(causes, fmt.Sprintf("%s (%s confidence)", rc.Summary, rc.Confidence))
+		}
+		sb.WriteString("\n\nLikely root causes:\n")
+		sb.WriteString(bullets(causes))
+	}
+	if issues := append(critical, warning...); len(issues) > 0 {
+		sb.WriteString("\n\nIssues:\n")
+		sb.WriteString(bullets(issues))
+	}
+	msg.Text = strings.TrimSpace(sb.String())
+	msg.Fields = []Field{
+		{Name: "Critical", Value: fmt.Sprint(len(critical)), Short: true},
+		{Name: "Warnings", Value: fmt.Sprint(len(warning)), Short: true},
+	}
+	return msg
+}
+
+// bullets renders items as a list of at most maxListed lines
+func bullets(items []string) string {
+	var sb strings.Builder
+	for i, item := range items {
+		if i == maxListed {
+			fmt.Fprintf(&sb, "- ...and %d more\n", len(items)-maxListed)
+			break
+		}
+		fmt.Fprintf(&sb, "- %s\n", item)
+	}
+	return sb.String()
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func qualified(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
@@ -0,0 +1,269 @@
+// Package notify posts messages about clanker's work to Slack and Discord
+// incoming webhooks. Each sink is configured with the events it wants:
+//
+//	notify:
+//	  timeout: 10s
+//	  slack:
+//	    webhook_url: https://hooks.slack.com/services/...
+//	    events: [apply_failed, watch_alert]
+//	  discord:
+//	    webhook_url: https://discord.com/api/webhooks/...
+//
+// A sink without events receives all of them. Sending is best effort: a
+// failed post is reported and never fails the command that triggered it.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Event identifies what a message is about
+type Event string
+
+const (
+	EventPlanApplied    Event = "plan_applied"
+	EventApplyFailed    Event = "apply_failed"
+	EventWatchAlert     Event = "watch_alert"
+	EventClusterCreated Event = "cluster_created"
+)
+
+// Events are every event a sink can subscribe to
+var Events = []Event{EventPlanApplied, EventApplyFailed, EventWatchAlert, EventClusterCreated}
+
+// defaultTimeout bounds each post unless notify.timeout is set
+const defaultTimeout = 10 * time.Second
+
+// Level colours a message
+type Level string
+
+const (
+	LevelInfo    Level = "info"
+	LevelSuccess Level = "success"
+	LevelWarning Level = "warning"
+	LevelError   Level = "error"
+)
+
+// Field is a labelled value shown under a message
+type Field struct {
+	Name  string
+	Value string
+	// Short fields may be laid out side by side
+	Short bool
+}
+
+// Message is a chat message, rendered by each sink in its own format
+type Message struct {
+	Event Event
+	Level Level
+	Title string
+	// Text is the body, kept to the markdown Slack and Discord both
+	// render: `code` and "- " bullet lists
+	Text   string
+	Fields []Field
+}
+
+// Sender posts a message to one destination
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Sink is a sender and the events it receives
+type Sink struct {
+	Name   string
+	Sender Sender
+	// Events is empty to receive every event
+	Events []Event
+}
+
+// Wants reports whether the sink receives event
+func (s Sink) Wants(event Event) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifier sends messages to the sinks that want them
+type Notifier struct {
+	Sinks   []Sink
+	Timeout time.Duration
+}
+
+// FromConfig builds a Notifier from the notify section of the config file.
+// Unknown event names are ignored so a newer config still loads.
+func FromConfig() *Notifier {
+	n := &Notifier{Timeout: viper.GetDuration("notify.timeout")}
+	client := &http.Client{}
+	for _, name := range []string{"slack", "discord"} {
+		url := strings.TrimSpace(viper.GetString("notify." + name + ".webhook_url"))
+		if url == "" {
+			continue
+		}
+		sink := Sink{Name: name}
+		switch name {
+		case "slack":
+			sink.Sender = &Slack{URL: url, Client: client}
+		case "discord":
+			sink.Sender = &Discord{URL: url, Client: client}
+		}
+		for _, e := range viper.GetStringSlice("notify." + name + ".events") {
+			if event, ok := ParseEvent(e); ok {
+				sink.Events = append(sink.Events, event)
+			}
+		}
+		n.Sinks = append(n.Sinks, sink)
+	}
+	return n
+}
+
+// ParseEvent maps an event name to its Event
+func ParseEvent(name string) (Event, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, e := range Events {
+		if string(e) == name {
+			return e, true
+		}
+	}
+	return "", false
+}
+
+// Send posts msg to every sink that wants its event. All sinks are tried;
+// the returned error names each failure.
+func (n *Notifier) Send(ctx context.Context, msg Message) error {
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	var errs []error
+	for _, sink := range n.Sinks {
+		if !sink.Wants(msg.Event) {
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, timeout)
+		if err := sink.Sender.Send(sendCtx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s notification failed: %w", sink.Name, err))
+		}
+		cancel()
+	}
+	return errors.Join(errs...)
+}
+
+// Send posts msg to the sinks in the config file
+func Send(ctx context.Context, msg Message) error {
+	return FromConfig().Send(ctx, msg)
+}
+
+// Slack posts to a Slack incoming webhook
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *Slack) Send(ctx context.Context, msg Message) error {
+	attachment := map[string]any{
+		"color":     "#" + colorHex(msg.Level),
+		"title":     msg.Title,
+		"text":      msg.Text,
+		"mrkdwn_in": []string{"text"},
+	}
+	if len(msg.Fields) > 0 {
+		fields := make([]map[string]any, 0, len(msg.Fields))
+		for _, f := range msg.Fields {
+			fields = append(fields, map[string]any{"title": f.Name, "value": f.Value, "short": f.Short})
+		}
+		attachment["fields"] = fields
+	}
+	return postJSON(ctx, s.Client, s.URL, map[string]any{
+		"text":        msg.Title,
+		"attachments": []map[string]any{attachment},
+	})
+}
+
+// Discord limits an embed's description and each field value
+const (
+	discordDescriptionLimit = 4096
+	discordFieldLimit       = 1024
+)
+
+// Discord posts to a Discord webhook
+type Discord struct {
+	URL    string
+	Client *http.Client
+}
+
+func (d *Discord) Send(ctx context.Context, msg Message) error {
+	var color int64
+	fmt.Sscanf(colorHex(msg.Level), "%x", &color)
+	embed := map[string]any{
+		"title":       truncate(msg.Title, 256),
+		"description": truncate(msg.Text, discordDescriptionLimit),
+		"color":       color,
+	}
+	if len(msg.Fields) > 0 {
+		fields := make([]map[string]any, 0, len(msg.Fields))
+		for _, f := range msg.Fields {
+			fields = append(fields, map[string]any{"name": f.Name, "value": truncate(f.Value, discordFieldLimit), "inline": f.Short})
+		}
+		embed["fields"] = fields
+	}
+	return postJSON(ctx, d.Client, d.URL, map[string]any{"embeds": []map[string]any{embed}})
+}
+
+func colorHex(level Level) string {
+	switch level {
+	case LevelSuccess:
+		return "2eb67d"
+	case LevelWarning:
+		return "ecb22e"
+	case LevelError:
+		return "e01e5a"
+	}
+	return "439fe0"
+}
+
+func truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	return strings.ToValidUTF8(s[:limit-3], "") + "..."
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post message: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/k8s/cluster"
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/spf13/viper"
+)
+
+type recorder struct {
+	got []Message
+}
+
+func (r *recorder) Send(ctx context.Context, msg Message) error {
+	r.got = append(r.got, msg)
+	return nil
+}
+
+func TestFromConfigAndEventFilter(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("notify.slack.webhook_url", "https://hooks.slack.com/services/x")
+	viper.Set("notify.slack.events", []string{"apply_failed", "Watch_Alert", "nonsense"})
+	viper.Set("notify.discord.webhook_url", "https://discord.com/api/webhooks/y")
+
+	n := FromConfig()
+	if len(n.Sinks) != 2 || n.Sinks[0].Name != "slack" || n.Sinks[1].Name != "discord" {
+		t.Fatalf("sinks = %+v", n.Sinks)
+	}
+	if got := n.Sinks[0].Events; len(got) != 2 || got[0] != EventApplyFailed || got[1] != EventWatchAlert {
+		t.Errorf("slack events = %v", got)
+	}
+
+	slack, discord := &recorder{}, &recorder{}
+	n.Sinks[0].Sender, n.Sinks[1].Sender = slack, discord
+	for _, e := range Events {
+		if err := n.Send(context.Background(), Message{Event: e}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(slack.got) != 2 || len(discord.got) != 4 {
+		t.Errorf("slack got %d, discord got %d", len(slack.got), len(discord.got))
+	}
+}
+
+func TestSenders(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if r.URL.Path == "/broken" {
+			http.Error(w, "invalid_token", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	msg := Message{Event: EventApplyFailed, Level: LevelError, Title: "Plan apply failed", Text: "- `aws s3 mb`",
+		Fields: []Field{{Name: "Source", Value: "clanker ask --apply", Short: true}}}
+	if err := (&Slack{URL: srv.URL}).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&Discord{URL: srv.URL}).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	attachment := bodies[0]["attachments"].([]any)[0].(map[string]any)
+	if bodies[0]["text"] != "Plan apply failed" || attachment["color"] != "#e01e5a" || attachment["fields"].([]any)[0].(map[string]any)["title"] != "Source" {
+		t.Errorf("slack body = %v", bodies[0])
+	}
+	embed := bodies[1]["embeds"].([]any)[0].(map[string]any)
+	if embed["color"] != float64(0xe01e5a) || embed["description"] != "- `aws s3 mb`" || embed["fields"].([]any)[0].(map[string]any)["inline"] != true {
+		t.Errorf("discord body = %v", bodies[1])
+	}
+
+	err := (&Slack{URL: srv.URL + "/broken"}).Send(context.Background(), msg)
+	if err == nil || !strings.Contains(err.Error(), "403 Forbidden: invalid_token") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestPlanApplied(t *testing.T) {
+	plan := []byte(`{"version":1,"provider":"aws","summary":"Create the\nlogs bucket","commands":[
+		{"args":["aws","s3","mb","s3://logs"]},
+		{"args":["aws","rds","create-db-instance","--master-user-password","hunter2"]}]}`)
+
+	ok := PlanApplied("ask --apply", plan, nil)
+	if ok.Event != EventPlanApplied || ok.Level != LevelSuccess || ok.Title != "Plan applied: Create the logs bucket" {
+		t.Errorf("applied = %+v", ok)
+	}
+	if strings.Contains(ok.Text, "hunter2") || !strings.Contains(ok.Text, "- `aws s3 mb s3://logs`") {
+		t.Errorf("text = %q", ok.Text)
+	}
+
+	failed := PlanApplied("ask --apply", plan, errors.New("command 2 failed: exit status 254"))
+	if failed.Event != EventApplyFailed || failed.Level != LevelError || !strings.HasPrefix(failed.Text, "Error: `command 2 failed: exit status 254`") {
+		t.Errorf("failed = %+v", failed)
+	}
+
+	raw := PlanApplied("ask --apply", []byte("not json"), nil)
+	if raw.Title != "Plan applied" || raw.Text != "" || len(raw.Fields) != 1 {
+		t.Errorf("unparsed plan = %+v", raw)
+	}
+}
+
+func TestWatchAlert(t *testing.T) {
+	msg := WatchAlert(Alert{Check: "certs", Type: "cert-expiry", From: "ok", To: "critical", Summary: "1 of 3 certificates need attention",
+		Details: []string{"ACM api.example.com: expires in 5 days"}})
+	if msg.Level != LevelError || msg.Title != "certs is CRITICAL (was ok)" || msg.Text != "1 of 3 certificates need attention\n- ACM api.example.com: expires in 5 days" {
+		t.Errorf("alert = %+v", msg)
+	}
+	if msg := WatchAlert(Alert{Check: "certs", To: "ok"}); msg.Level != LevelSuccess || msg.Title != "certs is OK (was new)" {
+		t.Errorf("recovery = %+v", msg)
+	}
+}
+
+func TestClusterCreated(t *testing.T) {
+	msg := ClusterCreated("EKS", &cluster.ClusterInfo{Name: "prod", Status: "ACTIVE", Endpoint: "https://ABC.eks.amazonaws.com"}, "~/.kube/config")
+	var names []string
+	for _, f := range msg.Fields {
+		names = append(names, f.Name)
+	}
+	if msg.Title != "EKS cluster prod created" || strings.Join(names, ",") != "Status,Endpoint,Kubeconfig" {
+		t.Errorf("message = %+v", msg)
+	}
+}
+
+func TestDiagnosis(t *testing.T) {
+	var issues []sre.Issue
+	for i := 0; i < 10; i++ {
+		issues = append(issues, sre.Issue{Severity: sre.SeverityWarning, ResourceType: "pod", Namespace: "shop", ResourceName: "web", Message: "restarting"})
+	}
+	issues = append(issues, sre.Issue{Severity: sre.SeverityCritical, ResourceType: "pod", Namespace: "shop", ResourceName: "api", Message: "CrashLoopBackOff"})
+	msg := Diagnosis(EventWatchAlert, &sre.DiagnosticReport{Scope: "namespace", Namespace: "shop", Summary: "11 issues", Issues: issues,
+		RootCauses: []sre.RootCause{{Summary: "bad config map", Confidence: "high"}}})
+
+	if msg.Level != LevelError || msg.Title != "Diagnosis of namespace shop" {
+		t.Errorf("message = %+v", msg)
+	}
+	for _, s := range []string{"- bad config map (high confidence)", "Issues:\n- pod/shop/api: CrashLoopBackOff", "- ...and 3 more"} {
+		if !strings.Contains(msg.Text, s) {
+			t.Errorf("text missing %q:\n%s", s, msg.Text)
+		}
+	}
+}
+
+func TestTruncateKeepsUTF8(t *testing.T) {
+	if got := truncate(strings.Repeat("é", 10), 8); got != "éé..." {
+		t.Errorf("truncate = %q", got)
+	}
+}
@@ -1,15 +1,12 @@
 package notion
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/bgdnvk/clanker/internal/convhistory"
 )
 
 const (
@@ -46,15 +43,12 @@ func NewConversationHistory(workspaceName string) *ConversationHistory {
 func (h *ConversationHistory) AddEntry(question, answer, workspaceName string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.Entries = append(h.Entries, ConversationEntry{
+	h.Entries = convhistory.Append(h.Entries, ConversationEntry{
 		Timestamp:     time.Now(),
 		Question:      question,
 		Answer:        answer,
 		WorkspaceName: workspaceName,
-	})
-	if len(h.Entries) > MaxHistoryEntries {
-		h.Entries = h.Entries[len(h.Entries)-MaxHistoryEntries:]
-	}
+	}, MaxHistoryEntries)
 }
 
 func (h *ConversationHistory) UpdateAccountStatus(status *AccountStatus) {
@@ -103,45 +97,18 @@ func (h *ConversationHistory) GetAccountStatusContext() string {
 	)
 }
 
-func historyPath(workspaceName string) (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-	dir := filepath.Join(home, ".clanker")
-	if err := secfile.EnsurePrivateDir(dir); err != nil {
-		return "", err
-	}
-	return filepath.Join(dir, fmt.Sprintf("notion-%s.json", secfile.SafeSlug(workspaceName))), nil
+// Save persists the conversation history to ~/.clanker/conversations/notion_<workspacename>.json
+func (h *ConversationHistory) Save() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Entries = convhistory.FitSize(h.Entries, convhistory.MaxBytes())
+	return convhistory.Save("notion", h.WorkspaceName, h)
 }
 
+// Load loads conversation history from disk
 func (h *ConversationHistory) Load() error {
-	path, err := historyPath(h.WorkspaceName)
-	if err != nil {
-		return err
-	}
-	data, err := secfile.ReadPrivate(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	return json.Unmarshal(data, h)
-}
-
-func (h *ConversationHistory) Save() error {
-	path, err := historyPath(h.WorkspaceName)
-	if err != nil {
-		return err
-	}
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	data, err := json.MarshalIndent(h, "", "  ")
-	if err != nil {
-		return err
-	}
-	return secfile.WritePrivate(path, data)
+	_, err := convhistory.Load("notion", h.WorkspaceName, h)
+	return err
 }
@@ -23,13 +23,13 @@ func TestConversationHistory_RoundTrip(t *testing.T) {
 		t.Fatalf("save: %v", err)
 	}
 
-	// File should land under ~/.clanker/notion-MyWorkspace.json (safeSlug
+	// File should land under ~/.clanker/conversations/notion_MyWorkspace.json (safeSlug
 	// strips spaces + punctuation).
-	matches, _ := filepath.Glob(filepath.Join(tmp, ".clanker", "notion-*.json"))
+	matches, _ := filepath.Glob(filepath.Join(tmp, ".clanker", "conversations", "notion_*.json"))
 	if len(matches) != 1 {
 		t.Fatalf("expected one history file, got %v", matches)
 	}
-	if got := filepath.Base(matches[0]); got != "notion-MyWorkspace.json" {
+	if got := filepath.Base(matches[0]); got != "notion_MyWorkspace.json" {
 		t.Errorf("unexpected file name: %s", got)
 	}
 
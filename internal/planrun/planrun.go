@@ -0,0 +1,119 @@
+// Package planrun bounds and cancels the steps of a plan apply. Each step
+// runs under its own timeout, from the plan or --step-timeout, inside the
+// apply's context, which Ctrl-C cancels. Cancelling the context kills the
+// step's child process; the executor then stops, records how far it got
+// and returns an *InterruptedError saying how to resume.
+package planrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ErrTimedOut is wrapped by the error of a step that ran past its timeout
+var ErrTimedOut = errors.New("step timed out")
+
+// InterruptedError is returned by an executor that Ctrl-C stopped
+type InterruptedError struct {
+	// Step is the 1-based step that was running or about to run
+	Step  int
+	Total int
+	// Resume tells the user how to finish the apply
+	Resume string
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("interrupted at step %d/%d", e.Step, e.Total)
+}
+
+// Interrupt returns the error of an executor that keeps no checkpoint
+// when Ctrl-C stops it at step idx (0-based). Running the plan again
+// repeats the steps that finished, so Resume names them.
+func Interrupt(idx, total int) *InterruptedError {
+	var resume string
+	switch idx {
+	case 0:
+		resume = "no steps finished; run the same apply again to start over"
+	case 1:
+		resume = "step 1 finished; this plan keeps no checkpoint, so remove that step before applying it again"
+	default:
+		resume = fmt.Sprintf("steps 1-%d finished; this plan keeps no checkpoint, so remove those steps before applying it again", idx)
+	}
+	return &InterruptedError{Step: idx + 1, Total: total, Resume: resume}
+}
+
+// Interrupted reports whether ctx was cancelled by Ctrl-C (or by its
+// caller) rather than by a deadline
+func Interrupted(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
+
+// WithInterrupt returns a context that Ctrl-C and SIGTERM cancel. After
+// the first signal the default handling comes back, so a second Ctrl-C
+// exits at once. stop releases the signal handler.
+func WithInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// ParseTimeout parses a step's timeout field, a Go duration such as "90s"
+// or "10m". Empty means the step has none.
+func ParseTimeout(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid timeout %q (use a duration such as 90s or 10m)", raw)
+	}
+	return d, nil
+}
+
+// DefaultTimeout is --step-timeout (step_timeout), the timeout of steps
+// whose plan sets none. Zero means no limit.
+func DefaultTimeout() time.Duration {
+	return viper.GetDuration("step_timeout")
+}
+
+// Step returns the context to run one step in, bounded by timeout or else
+// DefaultTimeout, and a done func that releases it. done passes the
+// step's error through, replacing it with one wrapping ErrTimedOut when
+// the step ran out of time.
+func Step(ctx context.Context, timeout string) (context.Context, func(error) error, error) {
+	d, err := ParseTimeout(timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	if d == 0 {
+		d = DefaultTimeout()
+	}
+	if d == 0 {
+		stepCtx, cancel := context.WithCancel(ctx)
+		return stepCtx, func(err error) error {
+			cancel()
+			return err
+		}, nil
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, d)
+	return stepCtx, func(err error) error {
+		defer cancel()
+		if err != nil && ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w after %s: %v", ErrTimedOut, d, err)
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,94 @@
+package planrun
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestParseTimeout(t *testing.T) {
+	for raw, want := range map[string]time.Duration{"": 0, " 90s ": 90 * time.Second, "10m": 10 * time.Minute} {
+		if got, err := ParseTimeout(raw); err != nil || got != want {
+			t.Errorf("ParseTimeout(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"10", "ten minutes", "-5s"} {
+		if _, err := ParseTimeout(raw); err == nil {
+			t.Errorf("ParseTimeout(%q) accepted", raw)
+		}
+	}
+}
+
+// block stands in for a child process: it runs until ctx is done
+func block(ctx context.Context) error {
+	<-ctx.Done()
+	return errors.New("signal: killed")
+}
+
+func TestStepTimesOut(t *testing.T) {
+	ctx, done, err := Step(context.Background(), "20ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = done(block(ctx))
+	if !errors.Is(err, ErrTimedOut) || !strings.Contains(err.Error(), "after 20ms") {
+		t.Errorf("err = %v", err)
+	}
+
+	// Errors of steps that finish in time pass through unchanged
+	_, done, _ = Step(context.Background(), "1m")
+	failed := errors.New("exit status 1")
+	if err := done(failed); err != failed {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestStepUsesDefaultTimeout(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("step_timeout", "20ms")
+
+	ctx, done, err := Step(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := done(block(ctx)); !errors.Is(err, ErrTimedOut) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestStepInterrupted(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, done, err := Step(parent, "1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := done(block(ctx)); errors.Is(err, ErrTimedOut) {
+		t.Errorf("interrupt reported as timeout: %v", err)
+	}
+	if !Interrupted(parent) {
+		t.Error("Interrupted = false after cancel")
+	}
+
+	deadline, stop := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer stop()
+	<-deadline.Done()
+	if Interrupted(deadline) {
+		t.Error("deadline reported as interrupt")
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	err := Interrupt(3, 7)
+	if err.Error() != "interrupted at step 4/7" || !strings.Contains(err.Resume, "steps 1-3 finished") {
+		t.Errorf("err = %v, resume = %q", err, err.Resume)
+	}
+	if resume := Interrupt(0, 7).Resume; !strings.Contains(resume, "no steps finished") {
+		t.Errorf("resume = %q", resume)
+	}
+}
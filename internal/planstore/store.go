@@ -0,0 +1,255 @@
+// Package planstore keeps generated plans under ~/.clanker/plans so they can
+// be listed, inspected and applied by ID instead of being copy-pasted around
+// as JSON. Each plan is one file, <id>.json, holding the plan document and its
+// execution status.
+package planstore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+)
+
+// Status is where a stored plan is in its lifecycle
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusApplied Status = "applied"
+	StatusFailed  Status = "failed"
+)
+
+// Plan kinds
+const (
+	KindMaker = "maker"
+	KindK8s   = "k8s"
+)
+
+// Plan is a generated plan and its execution history
+type Plan struct {
+	ID         string          `json:"id"`
+	Kind       string          `json:"kind"`
+	Provider   string          `json:"provider,omitempty"`
+	Source     string          `json:"source"`
+	Question   string          `json:"question,omitempty"`
+	Summary    string          `json:"summary,omitempty"`
+	Status     Status          `json:"status"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	AppliedAt  *time.Time      `json:"appliedAt,omitempty"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Body       json.RawMessage `json:"plan"`
+}
+
+// Dir returns ~/.clanker/plans
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "plans"), nil
+}
+
+// Add stores a freshly generated plan as pending and returns it with its ID
+func Add(kind, provider, source, question, summary string, body []byte) (*Plan, error) {
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("plan is not valid JSON")
+	}
+	p := &Plan{
+		ID:        newID(time.Now()),
+		Kind:      kind,
+		Provider:  provider,
+		Source:    source,
+		Question:  question,
+		Summary:   summary,
+		Status:    StatusPending,
+		CreatedAt: time.Now().UTC(),
+		Body:      append(json.RawMessage(nil), body...),
+	}
+	if err := Save(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Save writes the plan to ~/.clanker/plans/<id>.json
+func Save(p *Plan) error {
+	if p.ID == "" {
+		return fmt.Errorf("plan id is required")
+	}
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := secfile.EnsurePrivateDir(dir); err != nil {
+		return fmt.Errorf("failed to create plans directory: %w", err)
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal plan: %w", err)
+	}
+	if err := secfile.WritePrivate(filepath.Join(dir, secfile.SafeSlug(p.ID)+".json"), data); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// List returns every stored plan, newest first. A missing store is empty.
+func List() ([]*Plan, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var plans []*Plan
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		p, err := read(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p)
+	}
+	sort.SliceStable(plans, func(i, j int) bool {
+		if !plans[i].CreatedAt.Equal(plans[j].CreatedAt) {
+			return plans[i].CreatedAt.After(plans[j].CreatedAt)
+		}
+		return plans[i].ID > plans[j].ID
+	})
+	return plans, nil
+}
+
+// Load returns the plan with the given ID; an unambiguous prefix is enough
+func Load(prefix string) (*Plan, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return nil, fmt.Errorf("plan id is required")
+	}
+	plans, err := List()
+	if err != nil {
+		return nil, err
+	}
+	var matches []*Plan
+	for _, p := range plans {
+		if p.ID == prefix {
+			return p, nil
+		}
+		if strings.HasPrefix(p.ID, prefix) {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no plan %q (see: clanker plan list)", prefix)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("plan id %q is ambiguous (%d matches)", prefix, len(matches))
+}
+
+// FindByBody returns the newest stored plan whose document matches body,
+// ignoring formatting, or nil when none does. It lets a plan applied from a
+// file or stdin update the status of the stored copy.
+func FindByBody(body []byte) (*Plan, error) {
+	want, ok := compact(body)
+	if !ok {
+		return nil, nil
+	}
+	plans, err := List()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range plans {
+		if got, ok := compact(p.Body); ok && bytes.Equal(got, want) {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// Delete removes a stored plan
+func Delete(id string) error {
+	p, err := Load(id)
+	if err != nil {
+		return err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, secfile.SafeSlug(p.ID)+".json")); err != nil {
+		return fmt.Errorf("failed to delete plan: %w", err)
+	}
+	return nil
+}
+
+// MarkStarted records that an apply of the plan began
+func MarkStarted(p *Plan) error {
+	now := time.Now().UTC()
+	p.AppliedAt = &now
+	p.FinishedAt = nil
+	p.Error = ""
+	return Save(p)
+}
+
+// MarkFinished records the outcome of an apply: applied on success, failed
+// with the error otherwise
+func MarkFinished(p *Plan, applyErr error) error {
+	now := time.Now().UTC()
+	p.FinishedAt = &now
+	if applyErr != nil {
+		p.Status = StatusFailed
+		p.Error = applyErr.Error()
+	} else {
+		p.Status = StatusApplied
+		p.Error = ""
+	}
+	return Save(p)
+}
+
+func read(path string) (*Plan, error) {
+	data, err := secfile.ReadPrivate(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan: %w", err)
+	}
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", filepath.Base(path), err)
+	}
+	return &p, nil
+}
+
+func compact(body []byte) ([]byte, bool) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, bytes.TrimSpace(body)); err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// newID returns a sortable ID such as 20261015-093000-3f9a
+func newID(now time.Time) string {
+	b := make([]byte, 2)
+	if _, err := rand.Read(b); err != nil {
+		return now.UTC().Format("20060102-150405.000000")
+	}
+	return now.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
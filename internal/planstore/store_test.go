@@ -0,0 +1,138 @@
+package planstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddListLoadDelete(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if plans, err := List(); err != nil || plans != nil {
+		t.Fatalf("List() on empty store = %v, %v", plans, err)
+	}
+
+	first, err := Add(KindMaker, "aws", "ask --maker", "create a bucket", "Create bucket", []byte(`{"provider":"aws","commands":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Add(KindK8s, "kubernetes", "ask k8s", "scale web", "Scale web", []byte(`{"summary":"Scale web"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	if err := Save(second); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(home, ".clanker", "plans", first.ID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("plan file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	plans, err := List()
+	if err != nil || len(plans) != 2 {
+		t.Fatalf("List() = %d plans, %v", len(plans), err)
+	}
+	if plans[0].ID != second.ID || plans[0].Status != StatusPending {
+		t.Errorf("newest plan = %+v", plans[0])
+	}
+
+	got, err := Load(first.ID)
+	if err != nil || got.Summary != "Create bucket" || !strings.Contains(string(got.Body), `"provider": "aws"`) {
+		t.Errorf("Load = %+v, %v", got, err)
+	}
+	if _, err := Load("nope"); err == nil {
+		t.Error("Load(nope) should fail")
+	}
+
+	if err := Delete(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if plans, _ := List(); len(plans) != 1 {
+		t.Errorf("after delete: %d plans", len(plans))
+	}
+}
+
+func TestLoadPrefix(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, id := range []string{"20261015-090000-aa11", "20261015-090000-aa22", "20261016-090000-bb33"} {
+		if err := Save(&Plan{ID: id, Kind: KindMaker, Status: StatusPending, Body: []byte(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p, err := Load("20261016"); err != nil || p.ID != "20261016-090000-bb33" {
+		t.Errorf("Load(prefix) = %v, %v", p, err)
+	}
+	if _, err := Load("20261015-090000-aa"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("ambiguous prefix error = %v", err)
+	}
+}
+
+func TestAppliedPlansDirIsIgnored(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	applied := filepath.Join(home, ".clanker", "plans", "applied")
+	if err := os.MkdirAll(applied, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(applied, "20260101-120000-api.json"), []byte(`{"id":"x"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if plans, err := List(); err != nil || len(plans) != 0 {
+		t.Errorf("List() = %v, %v", plans, err)
+	}
+}
+
+func TestStatusLifecycle(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	p, err := Add(KindK8s, "kubernetes", "ask k8s eks", "", "Create EKS cluster", []byte("{\n  \"summary\": \"Create EKS cluster\"\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := FindByBody([]byte(`{"summary":"Create EKS cluster"}`))
+	if err != nil || found == nil || found.ID != p.ID {
+		t.Fatalf("FindByBody = %v, %v", found, err)
+	}
+	if found, _ := FindByBody([]byte(`{"summary":"other"}`)); found != nil {
+		t.Errorf("FindByBody matched %s", found.ID)
+	}
+
+	if err := MarkStarted(found); err != nil {
+		t.Fatal(err)
+	}
+	if err := MarkFinished(found, errors.New("eksctl command failed")); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := Load(p.ID)
+	if got.Status != StatusFailed || got.Error != "eksctl command failed" || got.AppliedAt == nil || got.FinishedAt == nil {
+		t.Errorf("after failure = %+v", got)
+	}
+
+	if err := MarkStarted(got); err != nil {
+		t.Fatal(err)
+	}
+	if err := MarkFinished(got, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = Load(p.ID)
+	if got.Status != StatusApplied || got.Error != "" {
+		t.Errorf("after success = %+v", got)
+	}
+}
+
+func TestAddRejectsInvalidJSON(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if _, err := Add(KindMaker, "aws", "ask --maker", "", "", []byte("not json")); err == nil {
+		t.Error("Add should reject invalid JSON")
+	}
+}
@@ -0,0 +1,141 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ServiceName is the gRPC service plugins serve. Every method takes and
+// returns a google.protobuf.Struct holding the JSON form of the request
+// and response types, so a plugin in any language only needs the
+// well-known types (see agent.proto).
+const ServiceName = "clanker.plugin.v1.Agent"
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*Agent)(nil),
+	Methods: []grpc.MethodDesc{
+		method("Describe", func(a Agent, ctx context.Context, _ struct{}) (Info, error) { return a.Describe(ctx) }),
+		method("HandleQuery", Agent.HandleQuery),
+		method("GeneratePlan", Agent.GeneratePlan),
+		method("ApplyPlan", Agent.ApplyPlan),
+	},
+	Metadata: "agent.proto",
+}
+
+// method adapts one Agent method to a unary gRPC handler
+func method[Req, Resp any](name string, call func(Agent, context.Context, Req) (Resp, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			in := new(structpb.Struct)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, in any) (any, error) {
+				var req Req
+				if err := fromStruct(in.(*structpb.Struct), &req); err != nil {
+					return nil, err
+				}
+				resp, err := call(srv.(Agent), ctx, req)
+				if err != nil {
+					return nil, err
+				}
+				return toStruct(resp)
+			}
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/" + name}, handler)
+		},
+	}
+}
+
+// RegisterAgent serves agent on s
+func RegisterAgent(s *grpc.Server, agent Agent) {
+	s.RegisterService(&serviceDesc, agent)
+}
+
+// ReadOnly can be embedded by plugins that answer questions but do not
+// make plans
+type ReadOnly struct{}
+
+func (ReadOnly) GeneratePlan(ctx context.Context, req PlanRequest) (PlanResponse, error) {
+	return PlanResponse{}, status.Error(codes.Unimplemented, "this plugin does not make plans")
+}
+
+func (ReadOnly) ApplyPlan(ctx context.Context, req ApplyRequest) (ApplyResponse, error) {
+	return ApplyResponse{}, status.Error(codes.Unimplemented, "this plugin does not apply plans")
+}
+
+// client is the Agent of a plugin, called over conn
+type client struct {
+	conn *grpc.ClientConn
+}
+
+// NewClient returns the Agent served on conn
+func NewClient(conn *grpc.ClientConn) Agent {
+	return &client{conn: conn}
+}
+
+func (c *client) Describe(ctx context.Context) (Info, error) {
+	var resp Info
+	return resp, c.invoke(ctx, "Describe", struct{}{}, &resp)
+}
+
+func (c *client) HandleQuery(ctx context.Context, req QueryRequest) (QueryResponse, error) {
+	var resp QueryResponse
+	return resp, c.invoke(ctx, "HandleQuery", req, &resp)
+}
+
+func (c *client) GeneratePlan(ctx context.Context, req PlanRequest) (PlanResponse, error) {
+	var resp PlanResponse
+	return resp, c.invoke(ctx, "GeneratePlan", req, &resp)
+}
+
+func (c *client) ApplyPlan(ctx context.Context, req ApplyRequest) (ApplyResponse, error) {
+	var resp ApplyResponse
+	return resp, c.invoke(ctx, "ApplyPlan", req, &resp)
+}
+
+func (c *client) invoke(ctx context.Context, name string, req, resp any) error {
+	in, err := toStruct(req)
+	if err != nil {
+		return err
+	}
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, "/"+ServiceName+"/"+name, in, out); err != nil {
+		// The plugin's own error message, without the rpc error prefix
+		return fmt.Errorf("%s: %s", name, status.Convert(err).Message())
+	}
+	return fromStruct(out, resp)
+}
+
+func toStruct(v any) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(m)
+}
+
+func fromStruct(s *structpb.Struct, v any) error {
+	data, err := json.Marshal(s.AsMap())
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("unexpected plugin message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// The handshake follows hashicorp/go-plugin. The magic cookie tells a
+// plugin binary it was started by clanker rather than run by hand; it is
+// not a secret.
+const (
+	MagicCookieKey     = "CLANKER_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue   = "d3b2f7c4a1e94f0b8c6a5e2d9f1b7c3a"
+	ProtocolVersionKey = "CLANKER_PLUGIN_PROTOCOL_VERSION"
+	// ProtocolVersion is the version of the Agent service. It changes
+	// only when the service does in a way old plugins cannot serve.
+	ProtocolVersion = 1
+
+	coreProtocolVersion = 1
+)
+
+// startTimeout bounds how long a plugin has to print its handshake
+const startTimeout = 10 * time.Second
+
+// stopTimeout is how long a plugin has to exit after an interrupt before
+// it is killed
+const stopTimeout = 2 * time.Second
+
+// Plugin is a running plugin process and the Agent it serves
+type Plugin struct {
+	Agent
+	Config Config
+
+	cmd    *exec.Cmd
+	conn   *grpc.ClientConn
+	exited chan struct{}
+}
+
+// Launch starts the plugin in cfg and connects to it. Its stderr, and
+// anything it prints after the handshake, goes to logs. Close stops it.
+func Launch(ctx context.Context, cfg Config, logs io.Writer) (*Plugin, error) {
+	if logs == nil {
+		logs = io.Discard
+	}
+	cmd := exec.Command(cfg.Path, cfg.Args...)
+	cmd.Env = append(os.Environ(), cfg.Env...)
+	cmd.Env = append(cmd.Env,
+		MagicCookieKey+"="+MagicCookieValue,
+		ProtocolVersionKey+"="+strconv.Itoa(ProtocolVersion),
+	)
+	cmd.Stderr = logs
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", cfg.Name, err)
+	}
+
+	p := &Plugin{Config: cfg, cmd: cmd, exited: make(chan struct{})}
+	go func() {
+		_ = cmd.Wait()
+		pw.Close()
+		close(p.exited)
+	}()
+	handshake := make(chan string, 1)
+	go func() {
+		r := bufio.NewReader(pr)
+		line, _ := r.ReadString('\n')
+		handshake <- line
+		_, _ = io.Copy(logs, r)
+	}()
+
+	var line string
+	select {
+	case line = <-handshake:
+	case <-time.After(startTimeout):
+		p.Close()
+		return nil, fmt.Errorf("plugin %s did not complete its handshake within %s", cfg.Name, startTimeout)
+	case <-ctx.Done():
+		p.Close()
+		return nil, ctx.Err()
+	}
+	if strings.TrimSpace(line) == "" {
+		p.Close()
+		return nil, fmt.Errorf("plugin %s exited before its handshake", cfg.Name)
+	}
+	target, err := parseHandshake(line)
+	if err != nil {
+		p.Close()
+		return nil, fmt.Errorf("plugin %s: %w", cfg.Name, err)
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		p.Close()
+		return nil, fmt.Errorf("failed to connect to plugin %s: %w", cfg.Name, err)
+	}
+	p.conn = conn
+	p.Agent = NewClient(conn)
+	return p, nil
+}
+
+// Close disconnects from the plugin and stops its process: first with an
+// interrupt, then by killing it if it does not exit in time.
+func (p *Plugin) Close() {
+	if p.conn != nil {
+		_ = p.conn.Close()
+	}
+	if err := p.cmd.Process.Signal(os.Interrupt); err == nil {
+		select {
+		case <-p.exited:
+			return
+		case <-time.After(stopTimeout):
+		}
+	}
+	_ = p.cmd.Process.Kill()
+	<-p.exited
+}
+
+// parseHandshake turns the plugin's handshake line,
+// CORE-VERSION|APP-VERSION|NETWORK|ADDR|grpc, into a gRPC target
+func parseHandshake(line string) (string, error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 5 {
+		return "", fmt.Errorf("unrecognized handshake %q (is it a clanker plugin?)", strings.TrimSpace(line))
+	}
+	if parts[0] != strconv.Itoa(coreProtocolVersion) {
+		return "", fmt.Errorf("unsupported core protocol version %s", parts[0])
+	}
+	if parts[1] != strconv.Itoa(ProtocolVersion) {
+		return "", fmt.Errorf("plugin speaks protocol version %s, clanker speaks %d", parts[1], ProtocolVersion)
+	}
+	if parts[4] != "grpc" {
+		return "", fmt.Errorf("unsupported plugin protocol %q", parts[4])
+	}
+	switch network, addr := parts[2], parts[3]; network {
+	case "unix":
+		return "unix://" + addr, nil
+	case "tcp":
+		// Plugins are local processes; never dial out for one
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil || !net.ParseIP(host).IsLoopback() {
+			return "", fmt.Errorf("plugin address %q is not on loopback", addr)
+		}
+		return "passthrough:///" + addr, nil
+	default:
+		return "", fmt.Errorf("unsupported plugin network %q", network)
+	}
+}
+
+// Serve runs agent as a plugin until clanker stops it. Plugin binaries
+// call it from main:
+//
+//	func main() {
+//		if err := plugin.Serve(myAgent{}); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+func Serve(agent Agent) error {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		return errors.New("this binary is a clanker plugin: list it under plugins in ~/.clanker.yaml instead of running it directly")
+	}
+	if v := os.Getenv(ProtocolVersionKey); v != strconv.Itoa(ProtocolVersion) {
+		return fmt.Errorf("clanker speaks plugin protocol version %s, this plugin speaks %d", v, ProtocolVersion)
+	}
+
+	ln, cleanup, err := listen()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	srv := grpc.NewServer()
+	RegisterAgent(srv, agent)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+
+	fmt.Printf("%d|%d|%s|%s|grpc\n", coreProtocolVersion, ProtocolVersion, ln.Addr().Network(), ln.Addr().String())
+	return srv.Serve(ln)
+}
+
+// listen serves on a unix socket in a private temp directory, or on
+// loopback TCP where unix sockets are not available
+func listen() (net.Listener, func(), error) {
+	if runtime.GOOS == "windows" {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		return ln, func() {}, err
+	}
+	dir, err := os.MkdirTemp("", "clanker-plugin-")
+	if err != nil {
+		return nil, nil, err
+	}
+	ln, err := net.Listen("unix", filepath.Join(dir, "plugin.sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	return ln, func() { os.RemoveAll(dir) }, nil
+}
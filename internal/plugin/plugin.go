@@ -0,0 +1,190 @@
+// Package plugin runs third-party agents as separate processes that clanker
+// talks to over gRPC, so a team can ship an agent for their own system
+// (Datadog, Snowflake, ...) without forking clanker. Plugins are launched
+// and handshaken the way hashicorp/go-plugin does it: clanker starts the
+// binary with a magic cookie in its environment, the plugin prints the
+// address it serves on as its first line of stdout, and clanker dials it.
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+	"github.com/spf13/viper"
+)
+
+// Agent is what a plugin implements. Describe reports the plugin's name
+// and the keywords questions are routed to it by; the rest mirror what
+// clanker ask does with a built-in agent.
+type Agent interface {
+	Describe(ctx context.Context) (Info, error)
+	HandleQuery(ctx context.Context, req QueryRequest) (QueryResponse, error)
+	GeneratePlan(ctx context.Context, req PlanRequest) (PlanResponse, error)
+	ApplyPlan(ctx context.Context, req ApplyRequest) (ApplyResponse, error)
+}
+
+// Info describes a plugin
+type Info struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+}
+
+// QueryRequest is a question clanker ask routed to the plugin
+type QueryRequest struct {
+	Question string `json:"question"`
+	Profile  string `json:"profile,omitempty"`
+	Debug    bool   `json:"debug,omitempty"`
+}
+
+// QueryResponse is the plugin's answer, printed as is
+type QueryResponse struct {
+	Answer string `json:"answer"`
+}
+
+// PlanRequest asks the plugin for a maker plan
+type PlanRequest struct {
+	Question  string `json:"question"`
+	Destroyer bool   `json:"destroyer,omitempty"`
+	Debug     bool   `json:"debug,omitempty"`
+}
+
+// PlanResponse carries the plan. Its commands are whatever the plugin's
+// ApplyPlan understands; clanker only stores and shows them.
+type PlanResponse struct {
+	Plan *maker.Plan `json:"plan"`
+}
+
+// ApplyRequest asks the plugin to apply a plan it generated earlier
+type ApplyRequest struct {
+	Plan      *maker.Plan `json:"plan"`
+	Destroyer bool        `json:"destroyer,omitempty"`
+	Debug     bool        `json:"debug,omitempty"`
+}
+
+// ApplyResponse is the output of an apply, printed as is
+type ApplyResponse struct {
+	Output string `json:"output"`
+}
+
+// ProviderPrefix marks maker plans that a plugin generated and applies,
+// as in "plugin:datadog"
+const ProviderPrefix = "plugin:"
+
+// Config is one entry of the plugins list in the config file. Keywords
+// set here are used instead of the ones the plugin describes, so routing
+// does not need to start it.
+type Config struct {
+	Name     string   `mapstructure:"name" json:"name"`
+	Path     string   `mapstructure:"path" json:"path"`
+	Args     []string `mapstructure:"args" json:"args,omitempty"`
+	Env      []string `mapstructure:"env" json:"-"`
+	Keywords []string `mapstructure:"keywords" json:"keywords,omitempty"`
+}
+
+// binaryPrefix is the name discovered plugin binaries start with
+const binaryPrefix = "clanker-plugin-"
+
+var nameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// ValidName reports whether name can name a plugin: lowercase letters,
+// digits and dashes, so it is usable as an agent name and in routing.force
+func ValidName(name string) bool {
+	return nameRegex.MatchString(name)
+}
+
+// DefaultDir returns ~/.clanker/plugins
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "plugins"), nil
+}
+
+// FromConfig returns the plugins listed under plugins in the config file,
+// then the executables named clanker-plugin-<name> in plugins_dir
+// (default ~/.clanker/plugins) that the list does not already name.
+func FromConfig() ([]Config, error) {
+	var configs []Config
+	if err := viper.UnmarshalKey("plugins", &configs); err != nil {
+		return nil, fmt.Errorf("invalid plugins config: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i := range configs {
+		c := &configs[i]
+		c.Name = strings.ToLower(strings.TrimSpace(c.Name))
+		c.Path = expandHome(strings.TrimSpace(c.Path))
+		if !ValidName(c.Name) {
+			return nil, fmt.Errorf("plugin %q: name must be lowercase letters, digits and dashes", c.Name)
+		}
+		if c.Path == "" {
+			return nil, fmt.Errorf("plugin %s: path is required", c.Name)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("plugin %s is listed twice", c.Name)
+		}
+		seen[c.Name] = true
+	}
+
+	dir := expandHome(strings.TrimSpace(viper.GetString("plugins_dir")))
+	if dir == "" {
+		var err error
+		if dir, err = DefaultDir(); err != nil {
+			return configs, nil
+		}
+	}
+	discovered, err := Discover(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range discovered {
+		if !seen[c.Name] {
+			configs = append(configs, c)
+		}
+	}
+	return configs, nil
+}
+
+// Discover finds the executables named clanker-plugin-<name> in dir. A
+// missing dir has no plugins.
+func Discover(dir string) ([]Config, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins dir: %w", err)
+	}
+	var configs []Config
+	for _, e := range entries {
+		name, ok := strings.CutPrefix(e.Name(), binaryPrefix)
+		name = strings.TrimSuffix(name, ".exe")
+		if !ok || e.IsDir() || !ValidName(name) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.Mode()&0o111 == 0 {
+			continue
+		}
+		configs = append(configs, Config{Name: name, Path: filepath.Join(dir, e.Name())})
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+	return configs, nil
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[1:])
+		}
+	}
+	return p
+}
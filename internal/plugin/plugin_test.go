@@ -0,0 +1,169 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+	"github.com/spf13/viper"
+)
+
+// testAgent is the agent the helper plugin process serves
+type testAgent struct {
+	ReadOnly
+}
+
+func (testAgent) Describe(ctx context.Context) (Info, error) {
+	return Info{Name: "datadog", Description: "Datadog monitors", Version: "0.1.0", Keywords: []string{"datadog", "monitor"}}, nil
+}
+
+func (testAgent) HandleQuery(ctx context.Context, req QueryRequest) (QueryResponse, error) {
+	if req.Question == "fail" {
+		return QueryResponse{}, errors.New("datadog api key is not set")
+	}
+	return QueryResponse{Answer: "3 monitors alerting for " + req.Question + " in " + req.Profile}, nil
+}
+
+// planningAgent also makes and applies plans
+type planningAgent struct {
+	testAgent
+}
+
+func (planningAgent) GeneratePlan(ctx context.Context, req PlanRequest) (PlanResponse, error) {
+	return PlanResponse{Plan: &maker.Plan{Summary: "mute " + req.Question, Commands: []maker.Command{{Args: []string{"dd", "mute", "web"}}}}}, nil
+}
+
+func (planningAgent) ApplyPlan(ctx context.Context, req ApplyRequest) (ApplyResponse, error) {
+	return ApplyResponse{Output: "applied " + strings.Join(req.Plan.Commands[0].Args, " ")}, nil
+}
+
+// TestHelperPlugin is not a real test: Launch runs the test binary with
+// it selected to act as a plugin.
+func TestHelperPlugin(t *testing.T) {
+	switch os.Getenv("CLANKER_TEST_PLUGIN") {
+	case "read-only":
+		_ = Serve(testAgent{})
+	case "planning":
+		_ = Serve(planningAgent{})
+	case "bad-handshake":
+		os.Stdout.WriteString("hello\n")
+	default:
+		return
+	}
+	os.Exit(0)
+}
+
+func helperConfig(kind string) Config {
+	return Config{Name: "datadog", Path: os.Args[0], Args: []string{"-test.run=^TestHelperPlugin$"}, Env: []string{"CLANKER_TEST_PLUGIN=" + kind}}
+}
+
+func TestLaunchReadOnlyPlugin(t *testing.T) {
+	ctx := context.Background()
+	p, err := Launch(ctx, helperConfig("read-only"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	info, err := p.Describe(ctx)
+	if err != nil || info.Name != "datadog" || strings.Join(info.Keywords, ",") != "datadog,monitor" {
+		t.Fatalf("info = %+v, %v", info, err)
+	}
+	resp, err := p.HandleQuery(ctx, QueryRequest{Question: "web", Profile: "prod"})
+	if err != nil || resp.Answer != "3 monitors alerting for web in prod" {
+		t.Errorf("answer = %q, %v", resp.Answer, err)
+	}
+	if _, err := p.HandleQuery(ctx, QueryRequest{Question: "fail"}); err == nil || err.Error() != "HandleQuery: datadog api key is not set" {
+		t.Errorf("error = %v", err)
+	}
+	if _, err := p.GeneratePlan(ctx, PlanRequest{Question: "web"}); err == nil || !strings.Contains(err.Error(), "does not make plans") {
+		t.Errorf("plan error = %v", err)
+	}
+}
+
+func TestLaunchPlanningPlugin(t *testing.T) {
+	ctx := context.Background()
+	p, err := Launch(ctx, helperConfig("planning"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	plan, err := p.GeneratePlan(ctx, PlanRequest{Question: "web alerts"})
+	if err != nil || plan.Plan.Summary != "mute web alerts" || len(plan.Plan.Commands) != 1 {
+		t.Fatalf("plan = %+v, %v", plan.Plan, err)
+	}
+	applied, err := p.ApplyPlan(ctx, ApplyRequest{Plan: plan.Plan})
+	if err != nil || applied.Output != "applied dd mute web" {
+		t.Errorf("apply = %q, %v", applied.Output, err)
+	}
+}
+
+func TestLaunchRejectsBadHandshake(t *testing.T) {
+	if _, err := Launch(context.Background(), helperConfig("bad-handshake"), nil); err == nil || !strings.Contains(err.Error(), "unrecognized handshake") {
+		t.Errorf("error = %v", err)
+	}
+	exits := Config{Name: "datadog", Path: "/bin/sh", Args: []string{"-c", "exit 1"}}
+	if _, err := Launch(context.Background(), exits, nil); err == nil || !strings.Contains(err.Error(), "exited before its handshake") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestParseHandshake(t *testing.T) {
+	for line, want := range map[string]string{
+		"1|1|unix|/tmp/clanker-plugin-1/plugin.sock|grpc\n": "unix:///tmp/clanker-plugin-1/plugin.sock",
+		"1|1|tcp|127.0.0.1:4321|grpc":                       "passthrough:///127.0.0.1:4321",
+	} {
+		if got, err := parseHandshake(line); err != nil || got != want {
+			t.Errorf("%q = %q, %v", line, got, err)
+		}
+	}
+	for _, line := range []string{"1|2|unix|/x|grpc", "1|1|tcp|10.0.0.5:4321|grpc", "1|1|unix|/x|netrpc", "1|1|udp|x|grpc"} {
+		if _, err := parseHandshake(line); err == nil {
+			t.Errorf("%q accepted", line)
+		}
+	}
+}
+
+func TestFromConfigAndDiscover(t *testing.T) {
+	dir := t.TempDir()
+	for name, mode := range map[string]os.FileMode{
+		"clanker-plugin-snowflake": 0o755,
+		"clanker-plugin-datadog":   0o755,
+		"clanker-plugin-notes.txt": 0o644,
+		"other-binary":             0o755,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("plugins_dir", dir)
+	viper.Set("plugins", []map[string]any{{"name": "Datadog", "path": "/opt/dd/plugin", "keywords": []string{"datadog"}}})
+
+	configs, err := FromConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 2 || configs[0].Name != "datadog" || configs[0].Path != "/opt/dd/plugin" || configs[1].Name != "snowflake" {
+		t.Errorf("configs = %+v", configs)
+	}
+
+	viper.Set("plugins", []map[string]any{{"name": "data dog", "path": "/x"}})
+	if _, err := FromConfig(); err == nil {
+		t.Error("invalid name accepted")
+	}
+}
+
+func TestServeRequiresMagicCookie(t *testing.T) {
+	t.Setenv(MagicCookieKey, "")
+	if err := Serve(testAgent{}); err == nil || !strings.Contains(err.Error(), "clanker plugin") {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+// Package pulumi reads Pulumi stacks, previews and stack outputs through the
+// pulumi CLI, the way package terraform does for Terraform workspaces.
+package pulumi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	stackCommandTimeout = 30 * time.Second
+	previewTimeout      = 3 * time.Minute
+)
+
+// Client runs the pulumi CLI in one project directory, against one stack
+// or the project's selected stack
+type Client struct {
+	project string
+	path    string
+	stack   string
+}
+
+// Stack is one entry of `pulumi stack ls --json`
+type Stack struct {
+	Name             string `json:"name"`
+	Current          bool   `json:"current"`
+	LastUpdate       string `json:"lastUpdate,omitempty"`
+	UpdateInProgress bool   `json:"updateInProgress,omitempty"`
+	ResourceCount    *int   `json:"resourceCount,omitempty"`
+	URL              string `json:"url,omitempty"`
+}
+
+// PreviewStep is one resource a preview would touch
+type PreviewStep struct {
+	Op   string `json:"op"`
+	URN  string `json:"urn"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// Preview summarizes `pulumi preview --json`
+type Preview struct {
+	Stack         string         `json:"stack"`
+	ChangeSummary map[string]int `json:"changeSummary"`
+	Steps         []PreviewStep  `json:"steps,omitempty"`
+	Errors        []string       `json:"errors,omitempty"`
+}
+
+// NewClient resolves project, either a key of pulumi.projects or a path
+// to a project directory. An empty project uses pulumi.default_project and
+// an empty stack the one configured for the project, if any.
+func NewClient(project, stack string) (*Client, error) {
+	stack = strings.TrimSpace(stack)
+	if looksLikePath(project) {
+		if expanded, ok := expandPath(project); ok {
+			return &Client{project: "local", path: expanded, stack: stack}, nil
+		}
+	}
+
+	projects := viper.GetStringMap("pulumi.projects")
+	if len(projects) == 0 {
+		return nil, fmt.Errorf("no pulumi projects configured")
+	}
+	if project == "" {
+		project = viper.GetString("pulumi.default_project")
+	}
+	if project == "" && len(projects) == 1 {
+		for name := range projects {
+			project = name
+		}
+	}
+
+	projectData, exists := projects[project]
+	if !exists {
+		return nil, fmt.Errorf("pulumi project '%s' not found in configuration", project)
+	}
+	config, ok := projectData.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("pulumi project '%s' has invalid configuration format", project)
+	}
+	path, ok := config["path"].(string)
+	if !ok || strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("pulumi project '%s' has no path configured", project)
+	}
+	if expanded, ok := expandPath(path); ok {
+		path = expanded
+	}
+	if stack == "" {
+		configured, _ := config["stack"].(string)
+		stack = strings.TrimSpace(configured)
+	}
+	return &Client{project: project, path: path, stack: stack}, nil
+}
+
+// Project is the configured project name, or "local" for a path
+func (c *Client) Project() string {
+	return c.project
+}
+
+// Path is the project directory
+func (c *Client) Path() string {
+	return c.path
+}
+
+// Stack is the stack commands run against; empty means the selected one
+func (c *Client) Stack() string {
+	return c.stack
+}
+
+// ListStacks runs `pulumi stack ls --json`
+func (c *Client) ListStacks(ctx context.Context) ([]Stack, error) {
+	output, err := c.run(ctx, stackCommandTimeout, "stack", "ls", "--json")
+	if err != nil {
+		return nil, err
+	}
+	var stacks []Stack
+	if err := json.Unmarshal(output, &stacks); err != nil {
+		return nil, fmt.Errorf("failed to parse pulumi stacks: %w", err)
+	}
+	return stacks, nil
+}
+
+// Preview runs `pulumi preview --json` and keeps the steps that change a
+// resource. A failed preview that still printed its JSON is returned with
+// its error diagnostics rather than as an error.
+func (c *Client) Preview(ctx context.Context) (*Preview, error) {
+	output, err := c.run(ctx, previewTimeout, c.withStack("preview", "--json", "--non-interactive")...)
+	preview, parseErr := ParsePreview(output)
+	if parseErr != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, parseErr
+	}
+	if err != nil && len(preview.Errors) == 0 {
+		return nil, err
+	}
+	preview.Stack = c.stack
+	return preview, nil
+}
+
+// Outputs runs `pulumi stack output --json`. Secret outputs stay masked.
+func (c *Client) Outputs(ctx context.Context) (map[string]any, error) {
+	output, err := c.run(ctx, stackCommandTimeout, c.withStack("stack", "output", "--json")...)
+	if err != nil {
+		return nil, err
+	}
+	outputs := map[string]any{}
+	if err := json.Unmarshal(output, &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse pulumi stack outputs: %w", err)
+	}
+	return outputs, nil
+}
+
+// GetRelevantContext gathers stacks and outputs for an ask, and a preview
+// when the question is about changes
+func (c *Client) GetRelevantContext(ctx context.Context, question string) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Pulumi project: %s\nPath: %s\n", c.project, c.path)
+	if c.stack != "" {
+		fmt.Fprintf(&sb, "Stack: %s\n", c.stack)
+	}
+
+	if stacks, err := c.ListStacks(ctx); err == nil {
+		sb.WriteString("\nPulumi Stacks:\n")
+		sb.WriteString(FormatStacks(stacks))
+	} else {
+		fmt.Fprintf(&sb, "\nPulumi Stacks: unavailable (%v)\n", err)
+	}
+
+	if outputs, err := c.Outputs(ctx); err == nil {
+		sb.WriteString("\nStack Outputs:\n")
+		sb.WriteString(FormatOutputs(outputs))
+	}
+
+	questionLower := strings.ToLower(question)
+	for _, keyword := range []string{"preview", "plan", "change", "diff", "drift", "pending"} {
+		if strings.Contains(questionLower, keyword) {
+			if preview, err := c.Preview(ctx); err == nil {
+				sb.WriteString("\n")
+				sb.WriteString(preview.Format())
+			} else {
+				fmt.Fprintf(&sb, "\nPulumi preview failed: %v\n", err)
+			}
+			break
+		}
+	}
+	return sb.String(), nil
+}
+
+func (c *Client) withStack(args ...string) []string {
+	if c.stack != "" {
+		args = append(args, "--stack", c.stack)
+	}
+	return args
+}
+
+func (c *Client) run(ctx context.Context, timeout time.Duration, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "pulumi", args...)
+	cmd.Dir = c.path
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return output, fmt.Errorf("pulumi %s failed: %w\nOutput: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// ParsePreview reads the output of `pulumi preview --json`
+func ParsePreview(data []byte) (*Preview, error) {
+	var out struct {
+		Steps []struct {
+			Op  string `json:"op"`
+			URN string `json:"urn"`
+		} `json:"steps"`
+		Diagnostics []struct {
+			Message  string `json:"message"`
+			Severity string `json:"severity"`
+		} `json:"diagnostics"`
+		ChangeSummary map[string]int `json:"changeSummary"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse pulumi preview: %w", err)
+	}
+	preview := &Preview{ChangeSummary: out.ChangeSummary}
+	for _, step := range out.Steps {
+		if step.Op == "same" || step.Op == "read" {
+			continue
+		}
+		resourceType, name := ParseURN(step.URN)
+		if resourceType == "pulumi:pulumi:Stack" {
+			continue
+		}
+		preview.Steps = append(preview.Steps, PreviewStep{Op: step.Op, URN: step.URN, Type: resourceType, Name: name})
+	}
+	for _, d := range out.Diagnostics {
+		if d.Severity == "error" {
+			preview.Errors = append(preview.Errors, strings.TrimSpace(d.Message))
+		}
+	}
+	return preview, nil
+}
+
+// ParseURN returns the resource type and name of a URN such as
+// urn:pulumi:dev::shop::aws:s3/bucket:Bucket::assets. Component children
+// carry their parent types joined by $; the last one is the resource's.
+func ParseURN(urn string) (string, string) {
+	parts := strings.Split(urn, "::")
+	if len(parts) < 4 {
+		return "", urn
+	}
+	types := strings.Split(parts[2], "$")
+	return types[len(types)-1], strings.Join(parts[3:], "::")
+}
+
+// HasChanges reports whether the preview would change any resource
+func (p *Preview) HasChanges() bool {
+	for op, count := range p.ChangeSummary {
+		if op != "same" && count > 0 {
+			return true
+		}
+	}
+	return len(p.Steps) > 0
+}
+
+// Format renders the change counts, the changed resources and any errors
+func (p *Preview) Format() string {
+	var sb strings.Builder
+	stack := p.Stack
+	if stack == "" {
+		stack = "(selected stack)"
+	}
+	fmt.Fprintf(&sb, "Pulumi preview for stack %s\n", stack)
+	if len(p.Errors) > 0 {
+		sb.WriteString("Preview failed:\n")
+		for _, e := range p.Errors {
+			fmt.Fprintf(&sb, "  - %s\n", e)
+		}
+		return sb.String()
+	}
+	if !p.HasChanges() {
+		fmt.Fprintf(&sb, "No changes; %d resource(s) unchanged\n", p.ChangeSummary["same"])
+		return sb.String()
+	}
+
+	ops := make([]string, 0, len(p.ChangeSummary))
+	for op := range p.ChangeSummary {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	counts := make([]string, 0, len(ops))
+	for _, op := range ops {
+		if op != "same" {
+			counts = append(counts, fmt.Sprintf("%d to %s", p.ChangeSummary[op], op))
+		}
+	}
+	if same := p.ChangeSummary["same"]; same > 0 {
+		counts = append(counts, fmt.Sprintf("%d unchanged", same))
+	}
+	fmt.Fprintf(&sb, "Changes: %s\n", strings.Join(counts, ", "))
+	for _, step := range p.Steps {
+		fmt.Fprintf(&sb, "  %-8s %s %s\n", step.Op, step.Type, step.Name)
+	}
+	return sb.String()
+}
+
+// FormatStacks renders one line per stack, marking the selected one
+func FormatStacks(stacks []Stack) string {
+	if len(stacks) == 0 {
+		return "  no stacks\n"
+	}
+	var sb strings.Builder
+	for _, s := range stacks {
+		marker := ""
+		if s.Current {
+			marker = " (selected)"
+		}
+		fmt.Fprintf(&sb, "  %s%s", s.Name, marker)
+		if s.ResourceCount != nil {
+			fmt.Fprintf(&sb, ", %d resource(s)", *s.ResourceCount)
+		}
+		switch {
+		case s.UpdateInProgress:
+			sb.WriteString(", update in progress")
+		case s.LastUpdate != "":
+			fmt.Fprintf(&sb, ", last update %s", s.LastUpdate)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// FormatOutputs renders outputs sorted by name, one per line
+func FormatOutputs(outputs map[string]any) string {
+	if len(outputs) == 0 {
+		return "  no outputs\n"
+	}
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sb strings.Builder
+	for _, name := range names {
+		value, _ := json.Marshal(outputs[name])
+		fmt.Fprintf(&sb, "  %s: %s\n", name, value)
+	}
+	return sb.String()
+}
+
+func looksLikePath(value string) bool {
+	value = strings.TrimSpace(value)
+	return value != "" && (strings.ContainsAny(value, "/\\") || strings.HasPrefix(value, "~") || strings.HasPrefix(value, "."))
+}
+
+func expandPath(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false
+	}
+	if strings.HasPrefix(raw, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			raw = filepath.Join(home, strings.TrimPrefix(raw, "~"))
+		}
+	}
+	path := filepath.Clean(os.ExpandEnv(raw))
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path, true
+	}
+	return "", false
+}
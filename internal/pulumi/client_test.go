@@ -0,0 +1,107 @@
+package pulumi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestNewClient(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+
+	dir := t.TempDir()
+	viper.Set("pulumi.projects", map[string]interface{}{
+		"api":    map[string]interface{}{"path": dir, "stack": "prod"},
+		"nopath": map[string]interface{}{"description": "missing"},
+		"bad":    "not-a-map",
+	})
+
+	c, err := NewClient("api", "")
+	if err != nil {
+		t.Fatalf("NewClient(api) error = %v", err)
+	}
+	if c.Path() != dir || c.Stack() != "prod" {
+		t.Errorf("client = %s@%s, want %s@prod", c.Path(), c.Stack(), dir)
+	}
+	if c, _ := NewClient("api", "dev"); c.Stack() != "dev" {
+		t.Errorf("stack argument did not override the configured stack: %s", c.Stack())
+	}
+	if c, err := NewClient(dir, ""); err != nil || c.Project() != "local" {
+		t.Errorf("NewClient(path) = %v, %v", c, err)
+	}
+
+	for project, want := range map[string]string{
+		"nopath":  "pulumi project 'nopath' has no path configured",
+		"bad":     "pulumi project 'bad' has invalid configuration format",
+		"missing": "pulumi project 'missing' not found in configuration",
+	} {
+		if _, err := NewClient(project, ""); err == nil || err.Error() != want {
+			t.Errorf("NewClient(%s) error = %v, want %q", project, err, want)
+		}
+	}
+}
+
+func TestParsePreview(t *testing.T) {
+	data := []byte(`{
+		"steps": [
+			{"op": "same", "urn": "urn:pulumi:dev::shop::pulumi:pulumi:Stack::shop-dev"},
+			{"op": "create", "urn": "urn:pulumi:dev::shop::aws:s3/bucket:Bucket::assets"},
+			{"op": "update", "urn": "urn:pulumi:dev::shop::my:app:Service$aws:lambda/function:Function::api"},
+			{"op": "same", "urn": "urn:pulumi:dev::shop::aws:iam/role:Role::api-role"}
+		],
+		"diagnostics": [{"message": "deprecated argument", "severity": "warning"}],
+		"changeSummary": {"create": 1, "update": 1, "same": 2}
+	}`)
+	preview, err := ParsePreview(data)
+	if err != nil {
+		t.Fatalf("ParsePreview() error = %v", err)
+	}
+	if len(preview.Steps) != 2 || len(preview.Errors) != 0 {
+		t.Fatalf("preview = %+v, want 2 changed steps and no errors", preview)
+	}
+	if got := preview.Steps[1]; got.Type != "aws:lambda/function:Function" || got.Name != "api" {
+		t.Errorf("component child step = %+v", got)
+	}
+
+	preview.Stack = "dev"
+	out := preview.Format()
+	for _, want := range []string{"Pulumi preview for stack dev", "Changes: 1 to create, 1 to update, 2 unchanged", "create   aws:s3/bucket:Bucket assets"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Format() missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestPreviewFormatNoChangesAndErrors(t *testing.T) {
+	none := &Preview{ChangeSummary: map[string]int{"same": 4}}
+	if none.HasChanges() || !strings.Contains(none.Format(), "No changes; 4 resource(s) unchanged") {
+		t.Errorf("Format() = %s", none.Format())
+	}
+
+	failed, err := ParsePreview([]byte(`{"diagnostics": [{"message": "missing required configuration variable 'region'", "severity": "error"}]}`))
+	if err != nil {
+		t.Fatalf("ParsePreview() error = %v", err)
+	}
+	if out := failed.Format(); !strings.Contains(out, "Preview failed:\n  - missing required configuration variable 'region'") {
+		t.Errorf("Format() = %s", out)
+	}
+}
+
+func TestFormatStacksAndOutputs(t *testing.T) {
+	count := 12
+	stacks := FormatStacks([]Stack{
+		{Name: "dev", Current: true, ResourceCount: &count, LastUpdate: "2026-10-14T09:00:00Z"},
+		{Name: "prod", UpdateInProgress: true},
+	})
+	want := "  dev (selected), 12 resource(s), last update 2026-10-14T09:00:00Z\n  prod, update in progress\n"
+	if stacks != want {
+		t.Errorf("FormatStacks() = %q, want %q", stacks, want)
+	}
+
+	outputs := FormatOutputs(map[string]any{"url": "https://shop.example.com", "dbPassword": "[secret]"})
+	if outputs != "  dbPassword: \"[secret]\"\n  url: \"https://shop.example.com\"\n" {
+		t.Errorf("FormatOutputs() = %q", outputs)
+	}
+}
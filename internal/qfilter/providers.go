@@ -0,0 +1,95 @@
+package qfilter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// kubernetesRegionLabel is the well-known node label carrying the region
+const kubernetesRegionLabel = "topology.kubernetes.io/region"
+
+var awsRegionPattern = regexp.MustCompile(`^[a-z]{2}-(?:gov-)?[a-z]+-\d$`)
+
+// AWSRegion returns the requested AWS region when the question names
+// exactly one, or "" otherwise.
+func (f Filter) AWSRegion() string {
+	var found []string
+	for _, region := range f.Regions {
+		if awsRegionPattern.MatchString(region) {
+			found = append(found, region)
+		}
+	}
+	if len(found) != 1 {
+		return ""
+	}
+	return found[0]
+}
+
+// EC2Filters renders tag qualifiers as server-side Describe* filters
+func (f Filter) EC2Filters() []ec2types.Filter {
+	filters := make([]ec2types.Filter, 0, len(f.Tags))
+	for _, k := range f.SortedTagKeys() {
+		filters = append(filters, ec2types.Filter{
+			Name:   aws.String("tag:" + k),
+			Values: []string{f.Tags[k]},
+		})
+	}
+	return filters
+}
+
+// KubernetesLabelSelector renders tag qualifiers as a label selector. For
+// nodes a single region qualifier also selects on the topology label.
+func (f Filter) KubernetesLabelSelector(resourceType string) string {
+	var parts []string
+	for _, k := range f.SortedTagKeys() {
+		parts = append(parts, fmt.Sprintf("%s=%s", k, f.Tags[k]))
+	}
+	if isKubernetesResource(resourceType, "node", "nodes", "no") && len(f.Regions) == 1 {
+		parts = append(parts, fmt.Sprintf("%s=%s", kubernetesRegionLabel, f.Regions[0]))
+	}
+	return strings.Join(parts, ",")
+}
+
+// KubernetesFieldSelector renders node and phase qualifiers as a field
+// selector. Only pods support these fields.
+func (f Filter) KubernetesFieldSelector(resourceType string) string {
+	if !isKubernetesResource(resourceType, "pod", "pods", "po") {
+		return ""
+	}
+	var parts []string
+	if f.Node != "" {
+		parts = append(parts, "spec.nodeName="+f.Node)
+	}
+	if f.Phase != "" {
+		parts = append(parts, "status.phase="+f.Phase)
+	}
+	return strings.Join(parts, ",")
+}
+
+func isKubernetesResource(resourceType string, names ...string) bool {
+	resourceType = strings.ToLower(strings.TrimSpace(resourceType))
+	for _, name := range names {
+		if resourceType == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CloudflareDNSParams renders tag qualifiers as list-DNS-records query
+// params. Cloudflare record tags use the "name:value" form.
+func (f Filter) CloudflareDNSParams() url.Values {
+	params := url.Values{}
+	for _, k := range f.SortedTagKeys() {
+		params.Add("tag", k+":"+f.Tags[k])
+	}
+	if len(f.Tags) > 1 {
+		params.Set("tag_match", "all")
+	}
+	return params
+}
@@ -0,0 +1,243 @@
+// Package qfilter compiles natural-language qualifiers such as "in
+// us-west-2", "tagged team=data", "older than 90 days", or "larger than
+// 1TiB" into a provider-neutral Filter that can be rendered as AWS API
+// filters, Kubernetes label/field selectors, or Cloudflare query params.
+package qfilter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter is the compiled form of the qualifiers found in a question. Zero
+// values mean "no constraint".
+type Filter struct {
+	Regions   []string
+	Tags      map[string]string
+	OlderThan time.Duration
+	NewerThan time.Duration
+	MinBytes  int64
+	MaxBytes  int64
+	Node      string // Kubernetes node name ("on node X")
+	Phase     string // Kubernetes pod phase ("pending pods")
+}
+
+const day = 24 * time.Hour
+
+var (
+	regionPattern = regexp.MustCompile(`\b((?:us|eu|ap|sa|ca|me|af|il|mx)-(?:gov-)?(?:north|south|east|west|central|northeast|northwest|southeast|southwest)-\d)\b|\b((?:us|europe|asia|australia|northamerica|southamerica|me|africa)-(?:north|south|east|west|central|northeast|northwest|southeast|southwest)\d)\b`)
+	tagPattern    = regexp.MustCompile(`(?i)\b(?:tagged|tag|labell?ed|label)(?:\s+with)?\s+([\w.:/-]+)\s*[=:]\s*([\w.:/@-]+)((?:\s*(?:,|and)\s*[\w.:/-]+\s*[=:]\s*[\w.:/@-]+)*)`)
+	tagPairRe     = regexp.MustCompile(`([\w.:/-]+)\s*[=:]\s*([\w.:/@-]+)`)
+	agePattern    = regexp.MustCompile(`(?i)\b(older than|more than|over|newer than|younger than|less than|within|in the last|in the past)\s+(\d+)\s*(minute|min|hour|hr|day|week|month|year)s?\b(\s+ago|\s+old)?`)
+	sizePattern   = regexp.MustCompile(`(?i)\b(larger|bigger|greater|more|over|above|smaller|less|under|below)(?:\s+than)?\s+(\d+(?:\.\d+)?)\s*(b|bytes?|kb|kib|mb|mib|gb|gib|tb|tib|pb|pib)\b`)
+	nodePattern   = regexp.MustCompile(`(?i)\bon\s+node\s+([a-z0-9][a-z0-9.-]*)`)
+	phasePattern  = regexp.MustCompile(`(?i)\b(pending|running|succeeded|failed|completed)\s+pods?\b`)
+)
+
+// Compile extracts qualifiers from a question. Unrecognised text is ignored.
+func Compile(question string) Filter {
+	f := Filter{Tags: map[string]string{}}
+
+	seenRegion := map[string]bool{}
+	for _, m := range regionPattern.FindAllStringSubmatch(strings.ToLower(question), -1) {
+		region := m[1]
+		if region == "" {
+			region = m[2]
+		}
+		if !seenRegion[region] {
+			seenRegion[region] = true
+			f.Regions = append(f.Regions, region)
+		}
+	}
+
+	for _, m := range tagPattern.FindAllString(question, -1) {
+		for _, pair := range tagPairRe.FindAllStringSubmatch(m, -1) {
+			f.Tags[pair[1]] = pair[2]
+		}
+	}
+
+	for _, m := range agePattern.FindAllStringSubmatch(question, -1) {
+		d := ageDuration(m[2], m[3])
+		switch op := strings.ToLower(m[1]); op {
+		case "older than":
+			f.OlderThan = d
+		case "newer than", "younger than", "within", "in the last", "in the past":
+			f.NewerThan = d
+		case "more than", "over":
+			// "more than 30 days old/ago" is an age qualifier; bare
+			// "more than 30 days" is too ambiguous to act on.
+			if strings.TrimSpace(m[4]) != "" {
+				f.OlderThan = d
+			}
+		case "less than":
+			if strings.TrimSpace(m[4]) != "" {
+				f.NewerThan = d
+			}
+		}
+	}
+
+	for _, m := range sizePattern.FindAllStringSubmatch(question, -1) {
+		bytes := sizeBytes(m[2], m[3])
+		switch strings.ToLower(m[1]) {
+		case "larger", "bigger", "greater", "more", "over", "above":
+			f.MinBytes = bytes
+		default:
+			f.MaxBytes = bytes
+		}
+	}
+
+	if m := nodePattern.FindStringSubmatch(question); m != nil {
+		f.Node = m[1]
+	}
+	if m := phasePattern.FindStringSubmatch(question); m != nil {
+		phase := strings.ToLower(m[1])
+		if phase == "completed" {
+			phase = "succeeded"
+		}
+		f.Phase = strings.ToUpper(phase[:1]) + phase[1:]
+	}
+	return f
+}
+
+func ageDuration(amount, unit string) time.Duration {
+	n, _ := strconv.Atoi(amount)
+	switch strings.ToLower(unit) {
+	case "minute", "min":
+		return time.Duration(n) * time.Minute
+	case "hour", "hr":
+		return time.Duration(n) * time.Hour
+	case "week":
+		return time.Duration(n) * 7 * day
+	case "month":
+		return time.Duration(n) * 30 * day
+	case "year":
+		return time.Duration(n) * 365 * day
+	default:
+		return time.Duration(n) * day
+	}
+}
+
+func sizeBytes(amount, unit string) int64 {
+	v, _ := strconv.ParseFloat(amount, 64)
+	multipliers := map[string]float64{
+		"b": 1, "byte": 1, "bytes": 1,
+		"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12, "pb": 1e15,
+		"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40, "pib": 1 << 50,
+	}
+	return int64(v * multipliers[strings.ToLower(unit)])
+}
+
+// IsEmpty reports whether no qualifier was found
+func (f Filter) IsEmpty() bool {
+	return len(f.Regions) == 0 && len(f.Tags) == 0 && f.OlderThan == 0 && f.NewerThan == 0 &&
+		f.MinBytes == 0 && f.MaxBytes == 0 && f.Node == "" && f.Phase == ""
+}
+
+// MatchesTags reports whether every requested tag is present. Values
+// compare case-insensitively.
+func (f Filter) MatchesTags(tags map[string]string) bool {
+	for k, want := range f.Tags {
+		got, ok := tags[k]
+		if !ok || !strings.EqualFold(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesAge reports whether a resource created at created satisfies the
+// age qualifiers. Unknown creation times always match.
+func (f Filter) MatchesAge(created, now time.Time) bool {
+	if created.IsZero() {
+		return true
+	}
+	age := now.Sub(created)
+	if f.OlderThan > 0 && age < f.OlderThan {
+		return false
+	}
+	if f.NewerThan > 0 && age > f.NewerThan {
+		return false
+	}
+	return true
+}
+
+// MatchesSize reports whether a size in bytes satisfies the size
+// qualifiers. Negative sizes mean unknown and always match.
+func (f Filter) MatchesSize(bytes int64) bool {
+	if bytes < 0 {
+		return true
+	}
+	if f.MinBytes > 0 && bytes <= f.MinBytes {
+		return false
+	}
+	if f.MaxBytes > 0 && bytes >= f.MaxBytes {
+		return false
+	}
+	return true
+}
+
+// SortedTagKeys returns tag keys in a stable order
+func (f Filter) SortedTagKeys() []string {
+	keys := make([]string, 0, len(f.Tags))
+	for k := range f.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Describe renders the qualifiers for notes shown to the user or model
+func (f Filter) Describe() string {
+	var parts []string
+	if len(f.Regions) > 0 {
+		parts = append(parts, "region "+strings.Join(f.Regions, "|"))
+	}
+	for _, k := range f.SortedTagKeys() {
+		parts = append(parts, fmt.Sprintf("tag %s=%s", k, f.Tags[k]))
+	}
+	if f.OlderThan > 0 {
+		parts = append(parts, "older than "+formatAge(f.OlderThan))
+	}
+	if f.NewerThan > 0 {
+		parts = append(parts, "newer than "+formatAge(f.NewerThan))
+	}
+	if f.MinBytes > 0 {
+		parts = append(parts, "larger than "+FormatBytes(f.MinBytes))
+	}
+	if f.MaxBytes > 0 {
+		parts = append(parts, "smaller than "+FormatBytes(f.MaxBytes))
+	}
+	if f.Node != "" {
+		parts = append(parts, "node "+f.Node)
+	}
+	if f.Phase != "" {
+		parts = append(parts, "phase "+f.Phase)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatAge(d time.Duration) string {
+	if d >= day && d%day == 0 {
+		return fmt.Sprintf("%dd", int(d/day))
+	}
+	return d.String()
+}
+
+// FormatBytes renders a byte count with binary units
+func FormatBytes(bytes int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	value := float64(bytes)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if value == float64(int64(value)) {
+		return fmt.Sprintf("%d%s", int64(value), units[i])
+	}
+	return fmt.Sprintf("%.1f%s", value, units[i])
+}
@@ -0,0 +1,84 @@
+package qfilter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompile(t *testing.T) {
+	f := Compile("show volumes in us-west-2 tagged team=data and env=prod older than 90 days larger than 1TiB")
+
+	if len(f.Regions) != 1 || f.Regions[0] != "us-west-2" {
+		t.Errorf("Regions = %v", f.Regions)
+	}
+	if f.Tags["team"] != "data" || f.Tags["env"] != "prod" {
+		t.Errorf("Tags = %v", f.Tags)
+	}
+	if f.OlderThan != 90*24*time.Hour {
+		t.Errorf("OlderThan = %v", f.OlderThan)
+	}
+	if f.MinBytes != 1<<40 {
+		t.Errorf("MinBytes = %d", f.MinBytes)
+	}
+	if got := f.Describe(); got != "region us-west-2, tag env=prod, tag team=data, older than 90d, larger than 1TiB" {
+		t.Errorf("Describe() = %q", got)
+	}
+}
+
+func TestCompileKubernetesQualifiers(t *testing.T) {
+	f := Compile("list pending pods on node ip-10-0-1-5.ec2.internal with label app=web")
+
+	if got := f.KubernetesLabelSelector("pods"); got != "app=web" {
+		t.Errorf("label selector = %q", got)
+	}
+	if got := f.KubernetesFieldSelector("pods"); got != "spec.nodeName=ip-10-0-1-5.ec2.internal,status.phase=Pending" {
+		t.Errorf("field selector = %q", got)
+	}
+	if got := f.KubernetesFieldSelector("deployments"); got != "" {
+		t.Errorf("deployments should not get pod field selectors, got %q", got)
+	}
+
+	nodes := Compile("nodes in us-east-1")
+	if got := nodes.KubernetesLabelSelector("nodes"); got != "topology.kubernetes.io/region=us-east-1" {
+		t.Errorf("node selector = %q", got)
+	}
+}
+
+func TestCompileAmbiguousAndEmpty(t *testing.T) {
+	if f := Compile("how many lambdas do we have"); !f.IsEmpty() {
+		t.Errorf("expected empty filter, got %+v", f)
+	}
+	// "more than 3 days" without "ago"/"old" is not an age qualifier
+	if f := Compile("functions that ran more than 3 days"); f.OlderThan != 0 {
+		t.Errorf("OlderThan = %v, want 0", f.OlderThan)
+	}
+	if f := Compile("buckets created in the last 7 days"); f.NewerThan != 7*24*time.Hour {
+		t.Errorf("NewerThan = %v", f.NewerThan)
+	}
+	if got := Compile("gke nodes in us-central1").AWSRegion(); got != "" {
+		t.Errorf("AWSRegion() = %q for a GCP region", got)
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := Filter{OlderThan: 30 * 24 * time.Hour, MinBytes: 1 << 30, Tags: map[string]string{"team": "data"}}
+
+	if f.MatchesAge(now.Add(-10*24*time.Hour), now) {
+		t.Error("10 day old resource should not match older than 30d")
+	}
+	if !f.MatchesAge(now.Add(-60*24*time.Hour), now) || !f.MatchesAge(time.Time{}, now) {
+		t.Error("old or unknown creation time should match")
+	}
+	if f.MatchesSize(1<<20) || !f.MatchesSize(2<<30) || !f.MatchesSize(-1) {
+		t.Error("size matching is wrong")
+	}
+	if !f.MatchesTags(map[string]string{"team": "Data", "x": "y"}) || f.MatchesTags(map[string]string{"team": "web"}) {
+		t.Error("tag matching is wrong")
+	}
+
+	params := Filter{Tags: map[string]string{"team": "web", "env": "prod"}}.CloudflareDNSParams()
+	if params.Encode() != "tag=env%3Aprod&tag=team%3Aweb&tag_match=all" {
+		t.Errorf("cloudflare params = %s", params.Encode())
+	}
+}
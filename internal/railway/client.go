@@ -901,6 +901,16 @@ func (c *Client) GetRelevantContext(ctx context.Context, question string) (strin
 		out.WriteString("\n")
 	}
 
+	if IsFailedDeployQuestion(question) {
+		failed, err := NewOps(c, time.Now()).FailedDeploymentContext(ctx, opScope(question))
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Latest Failed Deployment: %v", err))
+		} else {
+			out.WriteString(failed)
+			out.WriteString("\n")
+		}
+	}
+
 	if len(warnings) > 0 {
 		out.WriteString("Railway Warnings:\n")
 		for i, w := range warnings {
@@ -1,15 +1,12 @@
 package railway
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/bgdnvk/clanker/internal/convhistory"
 )
 
 // ConversationEntry represents a single Q&A exchange.
@@ -52,11 +49,7 @@ func (h *ConversationHistory) AddEntry(question, answer string) {
 		Answer:    answer,
 	}
 
-	h.Entries = append(h.Entries, entry)
-
-	if len(h.Entries) > MaxHistoryEntries {
-		h.Entries = h.Entries[len(h.Entries)-MaxHistoryEntries:]
-	}
+	h.Entries = convhistory.Append(h.Entries, entry, MaxHistoryEntries)
 }
 
 // GetRecentContext returns recent conversation context as a formatted string
@@ -80,114 +73,24 @@ func (h *ConversationHistory) GetRecentContext(maxEntries int) string {
 			sb.WriteString("\n")
 		}
 		sb.WriteString(fmt.Sprintf("Q: %s\n", entry.Question))
-		sb.WriteString(fmt.Sprintf("A: %s\n", truncateAnswer(entry.Answer, MaxAnswerLengthInContext)))
+		sb.WriteString(fmt.Sprintf("A: %s\n", convhistory.Truncate(entry.Answer, MaxAnswerLengthInContext)))
 	}
 
 	return sb.String()
 }
 
-// Save persists the conversation history to disk using atomic write
-// (temp file + rename). The mutex is only held while snapshotting and
-// marshalling state — disk I/O happens with the lock released so concurrent
-// callers aren't blocked on filesystem latency.
+// Save persists the conversation history to ~/.clanker/conversations/railway_<workspaceid>.json
 func (h *ConversationHistory) Save() error {
 	h.mu.Lock()
-	if len(h.Entries) > MaxHistoryEntries {
-		h.Entries = h.Entries[len(h.Entries)-MaxHistoryEntries:]
-	}
-	snapshot := struct {
-		Entries     []ConversationEntry `json:"entries"`
-		WorkspaceID string              `json:"workspace_id"`
-	}{
-		Entries:     append([]ConversationEntry(nil), h.Entries...),
-		WorkspaceID: h.WorkspaceID,
-	}
-	workspaceID := h.WorkspaceID
-	h.mu.Unlock()
-
-	data, err := json.MarshalIndent(snapshot, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal conversation history: %w", err)
-	}
-
-	dir, err := conversationDir()
-	if err != nil {
-		return err
-	}
-	if err := secfile.EnsurePrivateDir(dir); err != nil {
-		return fmt.Errorf("failed to create conversation directory: %w", err)
-	}
-
-	filename := filepath.Join(dir, fmt.Sprintf("railway_%s.json", secfile.SafeSlug(workspaceID)))
-
-	// Atomic write: temp + rename.
-	tmp := filename + ".tmp"
-	if err := secfile.WritePrivate(tmp, data); err != nil {
-		return fmt.Errorf("failed to write temp conversation file: %w", err)
-	}
-	if err := os.Rename(tmp, filename); err != nil {
-		_ = os.Remove(tmp)
-		return fmt.Errorf("failed to rename conversation file: %w", err)
-	}
-
-	return nil
+	defer h.mu.Unlock()
+	h.Entries = convhistory.FitSize(h.Entries, convhistory.MaxBytes())
+	return convhistory.Save("railway", h.WorkspaceID, h)
 }
 
-// Load loads conversation history from disk.
+// Load loads conversation history from disk
 func (h *ConversationHistory) Load() error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-
-	path, err := h.filePath()
-	if err != nil {
-		return err
-	}
-	data, err := secfile.ReadPrivate(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return fmt.Errorf("failed to read conversation file: %w", err)
-	}
-
-	var loaded struct {
-		Entries     []ConversationEntry `json:"entries"`
-		WorkspaceID string              `json:"workspace_id"`
-	}
-
-	if err := json.Unmarshal(data, &loaded); err != nil {
-		return fmt.Errorf("failed to parse conversation history: %w", err)
-	}
-
-	h.Entries = loaded.Entries
-	h.WorkspaceID = loaded.WorkspaceID
-
-	return nil
-}
-
-// filePath returns the on-disk path for this history file.
-func (h *ConversationHistory) filePath() (string, error) {
-	dir, err := conversationDir()
-	if err != nil {
-		return "", err
-	}
-	return filepath.Join(dir, fmt.Sprintf("railway_%s.json", secfile.SafeSlug(h.WorkspaceID))), nil
-}
-
-// conversationDir returns ~/.clanker/conversations.
-func conversationDir() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %w", err)
-	}
-	return filepath.Join(home, ".clanker", "conversations"), nil
-}
-
-// truncateAnswer truncates text to maxLen characters, adding an ellipsis
-// when truncated.
-func truncateAnswer(text string, maxLen int) string {
-	if len(text) <= maxLen {
-		return text
-	}
-	return text[:maxLen] + "..."
+	_, err := convhistory.Load("railway", h.WorkspaceID, h)
+	return err
 }
@@ -0,0 +1,375 @@
+package railway
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// OpsAPI is the part of Client the ops agent calls, so tests can stand in
+// for Railway.
+type OpsAPI interface {
+	ListProjects(ctx context.Context) ([]Project, error)
+	GetProject(ctx context.Context, projectID string) (*Project, error)
+	ListDeployments(ctx context.Context, projectID, environmentID, serviceID string, limit int) ([]Deployment, error)
+	ListDeploymentLogs(ctx context.Context, deploymentID string, buildLogs bool, limit int) ([]map[string]any, error)
+	ListVariables(ctx context.Context, projectID, environmentID, serviceID string) (map[string]string, error)
+}
+
+// buildLogTail is how many of a build's last log lines are shown; the error
+// that failed a build is almost always at the end.
+const buildLogTail = 40
+
+// Ops answers OpRequests. Reads run straight against the GraphQL API;
+// changes are built into plans by BuildOpPlan and only run through
+// `ask --apply`.
+type Ops struct {
+	api OpsAPI
+	now time.Time
+}
+
+// NewOps returns an ops agent over api. now dates deployments in listings.
+func NewOps(api OpsAPI, now time.Time) *Ops {
+	return &Ops{api: api, now: now}
+}
+
+// Target is a request's project, environment and service resolved to their
+// IDs. Environment and Service are nil when the project has none to pick.
+type Target struct {
+	Project     Project
+	Environment *Environment
+	Service     *Service
+}
+
+// Resolve finds the project, environment and service req names. With no
+// project named it uses the only project, with no environment named
+// production or else the first, and with no service named the only one.
+func (o *Ops) Resolve(ctx context.Context, req OpRequest) (*Target, error) {
+	projects, err := o.api.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var match *Project
+	switch {
+	case req.Project != "":
+		for i := range projects {
+			if strings.EqualFold(projects[i].Name, req.Project) || projects[i].ID == req.Project {
+				match = &projects[i]
+				break
+			}
+		}
+		if match == nil {
+			return nil, fmt.Errorf("no Railway project named %s (found: %s)", req.Project, projectNames(projects))
+		}
+	case len(projects) == 1:
+		match = &projects[0]
+	case len(projects) == 0:
+		return nil, fmt.Errorf("no Railway projects found")
+	default:
+		return nil, fmt.Errorf("name the Railway project, e.g. \"project %s\" (found: %s)", projects[0].Name, projectNames(projects))
+	}
+
+	project, err := o.api.GetProject(ctx, match.ID)
+	if err != nil {
+		return nil, err
+	}
+	t := &Target{Project: *project}
+	if t.Project.Name == "" {
+		t.Project.Name = match.Name
+	}
+
+	for i, env := range project.Environments {
+		if (req.Environment != "" && (strings.EqualFold(env.Name, req.Environment) || env.ID == req.Environment)) ||
+			(req.Environment == "" && strings.EqualFold(env.Name, "production")) {
+			t.Environment = &project.Environments[i]
+			break
+		}
+	}
+	if t.Environment == nil {
+		if req.Environment != "" {
+			return nil, fmt.Errorf("project %s has no environment named %s", t.Project.Name, req.Environment)
+		}
+		if len(project.Environments) > 0 {
+			t.Environment = &project.Environments[0]
+		}
+	}
+
+	switch {
+	case req.Service != "":
+		for i, svc := range project.Services {
+			if strings.EqualFold(svc.Name, req.Service) || svc.ID == req.Service {
+				t.Service = &project.Services[i]
+				break
+			}
+		}
+		if t.Service == nil {
+			return nil, fmt.Errorf("project %s has no service named %s", t.Project.Name, req.Service)
+		}
+	case len(project.Services) == 1:
+		t.Service = &project.Services[0]
+	}
+	return t, nil
+}
+
+// Read lists projects, deployments or variables, or fetches the build logs
+// of the deployment req resolves to, rendered as text.
+func (o *Ops) Read(ctx context.Context, req OpRequest) (string, error) {
+	switch req.Op {
+	case OpListProjects:
+		projects, err := o.api.ListProjects(ctx)
+		if err != nil {
+			return "", err
+		}
+		return o.formatProjects(projects), nil
+	case OpListDeployments:
+		t, deployments, err := o.deployments(ctx, req, 20)
+		if err != nil {
+			return "", err
+		}
+		return o.formatDeployments(t, deployments), nil
+	case OpBuildLogs:
+		t, d, err := o.latestDeployment(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		return o.buildReport(ctx, t, d), nil
+	case OpListVariables:
+		t, err := o.Resolve(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		if t.Environment == nil {
+			return "", fmt.Errorf("project %s has no environments", t.Project.Name)
+		}
+		serviceID, scope := "", "shared"
+		if t.Service != nil {
+			serviceID, scope = t.Service.ID, t.Service.Name
+		}
+		vars, err := o.api.ListVariables(ctx, t.Project.ID, t.Environment.ID, serviceID)
+		if err != nil {
+			return "", err
+		}
+		return formatVariables(t.Project.Name, t.Environment.Name, scope, vars), nil
+	}
+	return "", fmt.Errorf("%s is not a read operation", req.Op)
+}
+
+// deployments lists the latest deployments req scopes to. With no project
+// named, and more than one to pick from, it lists across projects and
+// leaves t nil.
+func (o *Ops) deployments(ctx context.Context, req OpRequest, limit int) (*Target, []Deployment, error) {
+	var t *Target
+	projectID, environmentID, serviceID := "", "", ""
+	if req.Project != "" || req.Service != "" || req.Environment != "" {
+		var err error
+		if t, err = o.Resolve(ctx, req); err != nil {
+			return nil, nil, err
+		}
+		projectID = t.Project.ID
+		// An unnamed environment or service widens the listing rather than
+		// defaulting
+		if req.Environment != "" && t.Environment != nil {
+			environmentID = t.Environment.ID
+		}
+		if req.Service != "" && t.Service != nil {
+			serviceID = t.Service.ID
+		}
+	}
+	deployments, err := o.api.ListDeployments(ctx, projectID, environmentID, serviceID, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, deployments, nil
+}
+
+// latestDeployment returns the newest deployment req scopes to, or the
+// newest failed one when req.Failed is set.
+func (o *Ops) latestDeployment(ctx context.Context, req OpRequest) (*Target, *Deployment, error) {
+	t, deployments, err := o.deployments(ctx, req, 20)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, d := range deployments {
+		if !req.Failed || deploymentFailed(d) {
+			return t, &deployments[i], nil
+		}
+	}
+	what := "deployments"
+	if req.Failed {
+		what = "failed deployments"
+	}
+	if t != nil {
+		return nil, nil, fmt.Errorf("no %s found for project %s", what, t.Project.Name)
+	}
+	return nil, nil, fmt.Errorf("no %s found", what)
+}
+
+// FailedDeploymentContext renders the latest failed deployment req scopes
+// to with the tail of its build logs, for the context agent to explain.
+func (o *Ops) FailedDeploymentContext(ctx context.Context, req OpRequest) (string, error) {
+	req.Failed = true
+	t, d, err := o.latestDeployment(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return "Latest Failed Deployment:\n" + o.buildReport(ctx, t, d), nil
+}
+
+// buildReport renders a deployment's status, commit and build log tail.
+func (o *Ops) buildReport(ctx context.Context, t *Target, d *Deployment) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Deployment %s of %s (%s, %s)\n", d.ID, serviceName(t, d.ServiceID), d.Status, environmentName(t, d.EnvironmentID))
+	if d.StaticURL != "" {
+		fmt.Fprintf(&sb, "  URL: https://%s\n", d.StaticURL)
+	}
+	if d.Meta.Branch != "" || d.Meta.CommitMessage != "" {
+		fmt.Fprintf(&sb, "  Commit: %s %s\n", d.Meta.Branch, firstLine(d.Meta.CommitMessage))
+	}
+	if d.CreatedAt != "" {
+		fmt.Fprintf(&sb, "  Created: %s\n", d.CreatedAt)
+	}
+
+	entries, err := o.api.ListDeploymentLogs(ctx, d.ID, true, 500)
+	if err != nil {
+		fmt.Fprintf(&sb, "  Build logs unavailable: %v\n", err)
+		return sb.String()
+	}
+	if len(entries) == 0 {
+		sb.WriteString("  No build logs returned.\n")
+		return sb.String()
+	}
+	if len(entries) > buildLogTail {
+		fmt.Fprintf(&sb, "  Build logs (last %d of %d lines):\n", buildLogTail, len(entries))
+		entries = entries[len(entries)-buildLogTail:]
+	} else {
+		sb.WriteString("  Build logs:\n")
+	}
+	for _, e := range entries {
+		msg, _ := e["message"].(string)
+		sev, _ := e["severity"].(string)
+		if sev != "" && !strings.EqualFold(sev, "info") {
+			fmt.Fprintf(&sb, "    [%s] %s\n", sev, strings.TrimRight(msg, "\n"))
+		} else {
+			fmt.Fprintf(&sb, "    %s\n", strings.TrimRight(msg, "\n"))
+		}
+	}
+	return sb.String()
+}
+
+func (o *Ops) formatProjects(projects []Project) string {
+	if len(projects) == 0 {
+		return "No Railway projects found.\n"
+	}
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tID\tUPDATED")
+	for _, p := range projects {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.Name, p.ID, o.age(p.UpdatedAt))
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+func (o *Ops) formatDeployments(t *Target, deployments []Deployment) string {
+	if len(deployments) == 0 {
+		return "No deployments found.\n"
+	}
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGE\tSERVICE\tENVIRONMENT\tSTATUS\tBRANCH\tCOMMIT")
+	for _, d := range deployments {
+		branch := d.Meta.Branch
+		if branch == "" {
+			branch = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", o.age(d.CreatedAt), serviceName(t, d.ServiceID), environmentName(t, d.EnvironmentID), d.Status, branch, firstLine(d.Meta.CommitMessage))
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+func formatVariables(project, environment, scope string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return fmt.Sprintf("No variables for %s in %s/%s.\n", scope, project, environment)
+	}
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Variables of %s in %s/%s (names only):\n", scope, project, environment)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "  %s\n", k)
+	}
+	return sb.String()
+}
+
+// age renders an RFC 3339 timestamp as a coarse age
+func (o *Ops) age(ts string) string {
+	at, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return "-"
+	}
+	d := o.now.Sub(at)
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+func deploymentFailed(d Deployment) bool {
+	return d.Status == "FAILED" || d.Status == "CRASHED"
+}
+
+// serviceName names a service by ID when t's project lists it
+func serviceName(t *Target, id string) string {
+	if t != nil {
+		for _, svc := range t.Project.Services {
+			if svc.ID == id {
+				return svc.Name
+			}
+		}
+	}
+	if id == "" {
+		return "-"
+	}
+	return id
+}
+
+// environmentName names an environment by ID when t's project lists it
+func environmentName(t *Target, id string) string {
+	if t != nil {
+		for _, env := range t.Project.Environments {
+			if env.ID == id {
+				return env.Name
+			}
+		}
+	}
+	if id == "" {
+		return "-"
+	}
+	return id
+}
+
+func projectNames(projects []Project) string {
+	names := make([]string, 0, len(projects))
+	for _, p := range projects {
+		names = append(names, p.Name)
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
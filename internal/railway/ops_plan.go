@@ -0,0 +1,90 @@
+package railway
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// SecretVariablePrefix prefixes the environment variables a variables plan
+// reads its values from at apply time, e.g. RAILWAY_SECRET_DATABASE_URL, so
+// a value is never written to a plan.
+const SecretVariablePrefix = "RAILWAY_SECRET_"
+
+// OpPlan is a change planned from an OpRequest, with the notes the caller
+// prints next to it.
+type OpPlan struct {
+	Plan  *maker.Plan
+	Notes []string
+}
+
+// BuildOpPlan plans a redeploy or a variables change on t's service as
+// railway CLI commands. Each plan links the working directory to the
+// service first, since the CLI acts on the linked project.
+func BuildOpPlan(req OpRequest, t *Target, question string, now time.Time) (*OpPlan, error) {
+	if t == nil || t.Service == nil {
+		return nil, fmt.Errorf("name the Railway service to change, e.g. \"service api\"")
+	}
+	if t.Environment == nil {
+		return nil, fmt.Errorf("project %s has no environments", t.Project.Name)
+	}
+	svc, env := t.Service.Name, t.Environment.Name
+	out := &OpPlan{}
+	var summary string
+	cmds := []maker.Command{{
+		Args:   []string{"railway", "link", "--project", t.Project.ID, "--environment", env, "--service", svc},
+		Reason: fmt.Sprintf("Link the working directory to %s/%s in %s", t.Project.Name, svc, env),
+	}}
+
+	switch req.Op {
+	case OpRedeploy:
+		summary = fmt.Sprintf("Redeploy %s in %s/%s", svc, t.Project.Name, env)
+		cmds = append(cmds, maker.Command{
+			Args:   []string{"railway", "redeploy", "--service", svc, "--yes"},
+			Reason: fmt.Sprintf("Rebuild and restart the latest deployment of %s", svc),
+		})
+		out.Notes = append(out.Notes, "A redeploy rebuilds the same commit, so it only helps when the failure was transient or a variable has since been fixed.")
+	case OpSetVariables:
+		if len(req.Vars) == 0 {
+			return nil, fmt.Errorf("name the variables to set in capitals, e.g. DATABASE_URL")
+		}
+		args := []string{"railway", "variable", "set"}
+		var vars []string
+		for _, name := range req.Vars {
+			args = append(args, fmt.Sprintf("%s=<%s%s>", name, SecretVariablePrefix, name))
+			vars = append(vars, SecretVariablePrefix+name)
+		}
+		args = append(args, "--service", svc, "--environment", env)
+		summary = fmt.Sprintf("Set %s on %s in %s/%s", strings.Join(req.Vars, ", "), svc, t.Project.Name, env)
+		cmds = append(cmds, maker.Command{Args: args, Reason: summary})
+		out.Notes = append(out.Notes,
+			fmt.Sprintf("Export %s before applying. Values are filled in at apply time, masked in the apply log and never written to the plan.", strings.Join(vars, ", ")),
+			"Railway redeploys the service when its variables change.")
+	case OpDeleteVariables:
+		if len(req.Vars) == 0 {
+			return nil, fmt.Errorf("name the variables to delete in capitals, e.g. DATABASE_URL")
+		}
+		for _, name := range req.Vars {
+			cmds = append(cmds, maker.Command{
+				Args:   []string{"railway", "variable", "delete", name, "--service", svc, "--environment", env},
+				Reason: fmt.Sprintf("Delete %s from %s in %s", name, svc, env),
+			})
+		}
+		summary = fmt.Sprintf("Delete %s from %s in %s/%s", strings.Join(req.Vars, ", "), svc, t.Project.Name, env)
+		out.Notes = append(out.Notes, "Deleting variables is destructive; apply with --destroyer.")
+	default:
+		return nil, fmt.Errorf("%s does not change anything", req.Op)
+	}
+
+	out.Plan = &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "railway",
+		Question:  question,
+		Summary:   summary,
+		Commands:  cmds,
+	}
+	return out, nil
+}
@@ -0,0 +1,137 @@
+package railway
+
+import (
+	"regexp"
+
+	"github.com/bgdnvk/clanker/internal/askparse"
+)
+
+// Op is a Railway operation an ask question can be answered with directly,
+// without going through the LLM context agent.
+type Op string
+
+const (
+	OpListProjects    Op = "list-projects"
+	OpListDeployments Op = "list-deployments"
+	OpBuildLogs       Op = "build-logs"
+	OpListVariables   Op = "list-variables"
+	OpRedeploy        Op = "redeploy"
+	OpSetVariables    Op = "set-variables"
+	OpDeleteVariables Op = "delete-variables"
+)
+
+// Mutates reports whether the op changes a service, in which case it is
+// planned rather than run.
+func (o Op) Mutates() bool {
+	switch o {
+	case OpRedeploy, OpSetVariables, OpDeleteVariables:
+		return true
+	}
+	return false
+}
+
+// OpRequest is a question translated into one Railway operation. Project,
+// Service and Environment are names (or IDs) as the question gives them.
+type OpRequest struct {
+	Op          Op
+	Project     string
+	Service     string
+	Environment string
+	// Failed asks for the latest failed deployment rather than the latest.
+	Failed bool
+	// Vars are the variable names to set or delete. Values are never read
+	// from the question.
+	Vars []string
+}
+
+var (
+	// notNames are words the name patterns pick up in ordinary phrasing
+	notNames = askparse.NotNames("each", "every", "railway", "latest", "last",
+		"variables", "variable", "vars", "env", "logs", "deployments", "deployment")
+
+	opProjectNames = askparse.Names{
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?:^|\s)--project[\s=]+([A-Za-z0-9][A-Za-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\bproject\s+(?:named\s+|called\s+)?["'` + "`" + `]?([a-z0-9][a-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9._-]*)\s+(?:railway\s+)?project\b`),
+		},
+		Skip: notNames,
+	}
+	opServiceNames = askparse.Names{
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?:^|\s)--service[\s=]+([A-Za-z0-9][A-Za-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\bservice\s+(?:named\s+|called\s+)?["'` + "`" + `]?([a-z0-9][a-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9._-]*)\s+(?:railway\s+)?service\b`),
+		},
+		Skip: notNames,
+	}
+	opEnvironmentNames = askparse.Names{
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?:^|\s)--environment[\s=]+([A-Za-z0-9][A-Za-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\benvironment\s+(?:named\s+|called\s+)?["'` + "`" + `]?([a-z0-9][a-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\b(production|staging)\b`),
+		},
+		Skip: notNames,
+	}
+
+	opVariablesRe = regexp.MustCompile(`(?i)\bvariables?\b|\bvars?\b|\benv\s+vars?\b`)
+	opSetRe       = regexp.MustCompile(`(?i)\b(?:set|add|create|update|rotate|put|change)\b`)
+	opDeleteRe    = regexp.MustCompile(`(?i)\b(?:unset|remove|delete|drop)\b`)
+	opRedeployRe  = regexp.MustCompile(`(?i)\bre-?deploy\b|\btrigger\b.*\bdeploy(?:ment)?\b`)
+	opLogsRe      = regexp.MustCompile(`(?i)\bbuild\s+(?:logs?|output)\b`)
+	opDeploysRe   = regexp.MustCompile(`(?i)\bdeployments\b|\bdeploys\b`)
+	opProjectsRe  = regexp.MustCompile(`(?i)\bprojects\b`)
+	opReadRe      = regexp.MustCompile(`(?i)\b(?:list|show|get|what|which|how\s+many|recent|latest|last)\b`)
+	opFailedRe    = regexp.MustCompile(`(?i)\bfail(?:ed|ing|ure)?\b|\bcrash(?:ed|ing)?\b|\berror(?:ed)?\b|\bbroke(?:n)?\b`)
+	// opWhyFailedRe asks why a deployment failed
+	opWhyFailedRe = regexp.MustCompile(`(?i)\bwhy\b.*\b(?:fail(?:ed|ing|s)?|error(?:ed)?|broke|crash(?:ed|ing)?)\b|\bwhat\s+(?:broke|went\s+wrong)\b`)
+)
+
+// ParseOpRequest reads the Railway operation question asks for. The zero
+// OpRequest means the question is better answered by the context agent.
+// Runtime logs are left to the context agent, which already gathers them.
+func ParseOpRequest(question string) OpRequest {
+	req := opScope(question)
+	req.Failed = opFailedRe.MatchString(question)
+	switch {
+	case opWhyFailedRe.MatchString(question):
+		// Answered by the context agent with the failed build's output
+	case opVariablesRe.MatchString(question):
+		req.Vars = askparse.KeyNames(question)
+		switch {
+		case opDeleteRe.MatchString(question):
+			req.Op = OpDeleteVariables
+		case opSetRe.MatchString(question):
+			req.Op = OpSetVariables
+		case req.Project != "" || req.Service != "":
+			req.Op = OpListVariables
+		}
+	case opRedeployRe.MatchString(question):
+		req.Op = OpRedeploy
+	case opLogsRe.MatchString(question):
+		req.Op = OpBuildLogs
+	case opDeploysRe.MatchString(question) && opReadRe.MatchString(question):
+		req.Op = OpListDeployments
+	case opProjectsRe.MatchString(question) && opReadRe.MatchString(question):
+		req.Op = OpListProjects
+	}
+	if req.Op == "" || (req.Op.Mutates() && askparse.AsksHowTo(question)) {
+		return OpRequest{}
+	}
+	return req
+}
+
+// IsFailedDeployQuestion reports whether question asks why a deployment
+// failed, so its build output belongs in the context.
+func IsFailedDeployQuestion(question string) bool {
+	return opWhyFailedRe.MatchString(question)
+}
+
+// opScope reads the project, service and environment question names
+func opScope(question string) OpRequest {
+	return OpRequest{
+		Project:     opProjectNames.Find(question),
+		Service:     opServiceNames.Find(question),
+		Environment: opEnvironmentNames.Find(question),
+	}
+}
@@ -0,0 +1,215 @@
+package railway
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// fakeOpsAPI serves one project with a web and a worker service and records
+// the scope deployments were listed with
+type fakeOpsAPI struct {
+	projects    []Project
+	deployments []Deployment
+	buildLogs   []map[string]any
+	listedWith  []string
+}
+
+func newFakeOpsAPI() *fakeOpsAPI {
+	return &fakeOpsAPI{projects: []Project{{ID: "prj_1", Name: "shop", UpdatedAt: "2026-10-14T12:00:00Z"}}}
+}
+
+func (f *fakeOpsAPI) ListProjects(ctx context.Context) ([]Project, error) { return f.projects, nil }
+
+func (f *fakeOpsAPI) GetProject(ctx context.Context, projectID string) (*Project, error) {
+	return &Project{
+		ID:           projectID,
+		Name:         "shop",
+		Environments: []Environment{{ID: "env_stg", Name: "staging"}, {ID: "env_prd", Name: "production"}},
+		Services:     []Service{{ID: "svc_web", Name: "web"}, {ID: "svc_wrk", Name: "worker"}},
+	}, nil
+}
+
+func (f *fakeOpsAPI) ListDeployments(ctx context.Context, projectID, environmentID, serviceID string, limit int) ([]Deployment, error) {
+	f.listedWith = []string{projectID, environmentID, serviceID}
+	return f.deployments, nil
+}
+
+func (f *fakeOpsAPI) ListDeploymentLogs(ctx context.Context, deploymentID string, buildLogs bool, limit int) ([]map[string]any, error) {
+	if !buildLogs {
+		return nil, fmt.Errorf("runtime logs requested")
+	}
+	return f.buildLogs, nil
+}
+
+func (f *fakeOpsAPI) ListVariables(ctx context.Context, projectID, environmentID, serviceID string) (map[string]string, error) {
+	if environmentID != "env_prd" || serviceID != "svc_web" {
+		return nil, fmt.Errorf("unexpected scope %s/%s", environmentID, serviceID)
+	}
+	return map[string]string{"STRIPE_KEY": "sk_live_secret", "DATABASE_URL": "postgres://secret"}, nil
+}
+
+func TestParseOpRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     OpRequest
+	}{
+		{"list my railway projects", OpRequest{Op: OpListProjects}},
+		{"show recent railway deployments of the web service in project shop", OpRequest{Op: OpListDeployments, Project: "shop", Service: "web"}},
+		{"show the build logs of the last failed railway deploy of service api", OpRequest{Op: OpBuildLogs, Service: "api", Failed: true}},
+		{"list railway variables for service web in staging", OpRequest{Op: OpListVariables, Service: "web", Environment: "staging"}},
+		{"set the STRIPE_KEY variable on the web service on railway", OpRequest{Op: OpSetVariables, Service: "web", Vars: []string{"STRIPE_KEY"}}},
+		{"delete railway variable OLD_TOKEN from service worker --environment staging", OpRequest{Op: OpDeleteVariables, Service: "worker", Environment: "staging", Vars: []string{"OLD_TOKEN"}}},
+		{"redeploy the api service on railway", OpRequest{Op: OpRedeploy, Service: "api"}},
+	}
+	for _, tt := range tests {
+		if got := ParseOpRequest(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseOpRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+
+	for _, q := range []string{
+		"why did my last railway deploy fail",
+		"how do I redeploy on railway",
+		"show railway logs for the api",
+	} {
+		if got := ParseOpRequest(q); got.Op != "" {
+			t.Errorf("ParseOpRequest(%q) = %+v, want it left to the context agent", q, got)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ops := NewOps(newFakeOpsAPI(), testNow)
+	got, err := ops.Resolve(context.Background(), OpRequest{Service: "WEB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Project.ID != "prj_1" || got.Environment.Name != "production" || got.Service.ID != "svc_web" {
+		t.Errorf("Resolve = %+v, want shop/production/web", got)
+	}
+	if _, err := ops.Resolve(context.Background(), OpRequest{Service: "api"}); err == nil {
+		t.Error("an unknown service should fail")
+	}
+
+	api := newFakeOpsAPI()
+	api.projects = append(api.projects, Project{ID: "prj_2", Name: "blog"})
+	if _, err := NewOps(api, testNow).Resolve(context.Background(), OpRequest{}); err == nil || !strings.Contains(err.Error(), "shop, blog") {
+		t.Errorf("err = %v, want the projects to pick from", err)
+	}
+}
+
+func TestOpsReadDeploymentsAndVariables(t *testing.T) {
+	api := newFakeOpsAPI()
+	api.deployments = []Deployment{{ID: "dep_1", Status: "FAILED", ServiceID: "svc_web", EnvironmentID: "env_prd", CreatedAt: "2026-10-15T10:00:00Z"}}
+	api.deployments[0].Meta.Branch = "main"
+	api.deployments[0].Meta.CommitMessage = "bump deps\n\nbody"
+	ops := NewOps(api, testNow)
+
+	out, err := ops.Read(context.Background(), OpRequest{Op: OpListDeployments, Service: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"prj_1", "", "svc_web"}; !reflect.DeepEqual(api.listedWith, want) {
+		t.Errorf("listed with %q, want %q", api.listedWith, want)
+	}
+	for _, want := range []string{"2h", "web", "production", "FAILED", "main", "bump deps"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("deployments output missing %q:\n%s", want, out)
+		}
+	}
+
+	out, err = ops.Read(context.Background(), OpRequest{Op: OpListVariables, Service: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "  DATABASE_URL\n  STRIPE_KEY\n") || strings.Contains(out, "secret") {
+		t.Errorf("variables output should list sorted names only:\n%s", out)
+	}
+}
+
+func TestFailedDeploymentContext(t *testing.T) {
+	api := newFakeOpsAPI()
+	api.deployments = []Deployment{
+		{ID: "dep_2", Status: "SUCCESS", ServiceID: "svc_wrk", EnvironmentID: "env_prd"},
+		{ID: "dep_1", Status: "FAILED", ServiceID: "svc_web", EnvironmentID: "env_prd"},
+	}
+	for i := 0; i < 45; i++ {
+		api.buildLogs = append(api.buildLogs, map[string]any{"message": fmt.Sprintf("step %d", i), "severity": "info"})
+	}
+	api.buildLogs = append(api.buildLogs, map[string]any{"message": "npm ERR! missing script: build", "severity": "error"})
+
+	out, err := NewOps(api, testNow).FailedDeploymentContext(context.Background(), OpRequest{Project: "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Latest Failed Deployment:",
+		"Deployment dep_1 of web (FAILED, production)",
+		"last 40 of 46 lines",
+		"[error] npm ERR! missing script: build",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "step 5\n") {
+		t.Errorf("output should keep only the tail:\n%s", out)
+	}
+}
+
+func TestBuildOpPlan(t *testing.T) {
+	target := &Target{
+		Project:     Project{ID: "prj_1", Name: "shop"},
+		Environment: &Environment{ID: "env_prd", Name: "production"},
+		Service:     &Service{ID: "svc_web", Name: "web"},
+	}
+	link := []string{"railway", "link", "--project", "prj_1", "--environment", "production", "--service", "web"}
+	tests := []struct {
+		req      OpRequest
+		wantArgs [][]string
+		summary  string
+	}{
+		{
+			OpRequest{Op: OpRedeploy},
+			[][]string{link, {"railway", "redeploy", "--service", "web", "--yes"}},
+			"Redeploy web in shop/production",
+		},
+		{
+			OpRequest{Op: OpSetVariables, Vars: []string{"STRIPE_KEY", "DATABASE_URL"}},
+			[][]string{link, {"railway", "variable", "set", "STRIPE_KEY=<RAILWAY_SECRET_STRIPE_KEY>", "DATABASE_URL=<RAILWAY_SECRET_DATABASE_URL>", "--service", "web", "--environment", "production"}},
+			"Set STRIPE_KEY, DATABASE_URL on web in shop/production",
+		},
+		{
+			OpRequest{Op: OpDeleteVariables, Vars: []string{"OLD_TOKEN"}},
+			[][]string{link, {"railway", "variable", "delete", "OLD_TOKEN", "--service", "web", "--environment", "production"}},
+			"Delete OLD_TOKEN from web in shop/production",
+		},
+	}
+	for _, tt := range tests {
+		out, err := BuildOpPlan(tt.req, target, "q", testNow)
+		if err != nil {
+			t.Fatalf("BuildOpPlan(%+v): %v", tt.req, err)
+		}
+		var args [][]string
+		for _, c := range out.Plan.Commands {
+			args = append(args, c.Args)
+		}
+		if !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("BuildOpPlan(%+v) args = %v, want %v", tt.req, args, tt.wantArgs)
+		}
+		if out.Plan.Provider != "railway" || out.Plan.Summary != tt.summary {
+			t.Errorf("plan = %q %q, want railway %q", out.Plan.Provider, out.Plan.Summary, tt.summary)
+		}
+	}
+
+	noService := &Target{Project: target.Project, Environment: target.Environment}
+	if _, err := BuildOpPlan(OpRequest{Op: OpRedeploy}, noService, "q", testNow); err == nil {
+		t.Error("a redeploy with no service should fail")
+	}
+}
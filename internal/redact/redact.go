@@ -0,0 +1,205 @@
+// Package redact scrubs credentials out of text before it leaves the
+// machine in an LLM prompt or is written to a conversation file. It covers
+// cloud access keys, API tokens, private key blocks, kubeconfig key data and
+// the values of Kubernetes Secret manifests, plus any extra patterns listed
+// under redact.patterns. Redaction is on by default; --no-redact (or
+// redact.disabled) turns it off for a run.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// Placeholder replaces every redacted value
+const Placeholder = "[REDACTED]"
+
+// rule is one built-in pattern. When keep is set, the first keep submatches
+// (the key name and separator) are written back before the placeholder.
+type rule struct {
+	name string
+	re   *regexp.Regexp
+	keep int
+}
+
+var builtinRules = []rule{
+	{name: "private key", re: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`)},
+	{name: "kubeconfig key data", re: regexp.MustCompile(`(?i)("?(?:client-key-data|client-certificate-data)"?\s*:\s*"?)[A-Za-z0-9+/=]{16,}`), keep: 1},
+	{name: "aws access key id", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "aws secret access key", re: regexp.MustCompile(`(?i)(secret_?access_?key"?\s*[:=]\s*"?)[A-Za-z0-9/+=]{40}`), keep: 1},
+	{name: "github token", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{name: "slack token", re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{name: "llm api key", re: regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`)},
+	{name: "google api key", re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{name: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{name: "bearer token", re: regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/-]{20,}=*`), keep: 1},
+	{name: "credential assignment", re: regexp.MustCompile(`(?i)((?:password|passwd|client_?secret|api_?key|access_?token|auth_?token|secret_?key)"?\s*[:=]\s*"?)[^\s"'\\,;]{6,}`), keep: 1},
+}
+
+var (
+	secretKindRe  = regexp.MustCompile(`(?m)^\s*"?kind"?\s*:\s*"?Secret"?\s*,?\s*$`)
+	secretDataRe  = regexp.MustCompile(`^(\s*)(?:data|stringData)\s*:\s*$`)
+	yamlEntryRe   = regexp.MustCompile(`^(\s*[^\s:#][^:]*:\s*)(\S.*)$`)
+	jsonSecretRe  = regexp.MustCompile(`("(?:data|stringData)"\s*:\s*\{)([^{}]*)(\})`)
+	jsonEntryRe   = regexp.MustCompile(`("[^"\\]+"\s*:\s*)"[^"\\]*"`)
+	yamlDocSepRe  = regexp.MustCompile(`(?m)^---\s*$`)
+	customPattern struct {
+		sync.Mutex
+		source []string
+		rules  []*regexp.Regexp
+	}
+)
+
+// Enabled reports whether redaction is on for this run
+func Enabled() bool {
+	return !viper.GetBool("redact.disabled")
+}
+
+// String redacts s when redaction is enabled
+func String(s string) string {
+	if !Enabled() || s == "" {
+		return s
+	}
+	return Apply(s)
+}
+
+// Apply redacts s unconditionally
+func Apply(s string) string {
+	for _, r := range builtinRules {
+		s = replace(r, s)
+	}
+	s = redactSecretManifests(s)
+	for _, re := range customRules() {
+		s = re.ReplaceAllString(s, Placeholder)
+	}
+	return s
+}
+
+func replace(r rule, s string) string {
+	if r.keep == 0 {
+		return r.re.ReplaceAllString(s, Placeholder)
+	}
+	return r.re.ReplaceAllStringFunc(s, func(match string) string {
+		sub := r.re.FindStringSubmatch(match)
+		var sb strings.Builder
+		for i := 1; i <= r.keep && i < len(sub); i++ {
+			sb.WriteString(sub[i])
+		}
+		sb.WriteString(Placeholder)
+		return sb.String()
+	})
+}
+
+// redactSecretManifests blanks the data and stringData values of Kubernetes
+// Secrets, in both the YAML and JSON kubectl output forms
+func redactSecretManifests(s string) string {
+	if !secretKindRe.MatchString(s) {
+		return s
+	}
+	if strings.Contains(s, `"kind"`) {
+		s = jsonSecretRe.ReplaceAllStringFunc(s, func(block string) string {
+			parts := jsonSecretRe.FindStringSubmatch(block)
+			return parts[1] + jsonEntryRe.ReplaceAllString(parts[2], `${1}"`+Placeholder+`"`) + parts[3]
+		})
+	}
+
+	docs := yamlDocSepRe.Split(s, -1)
+	seps := yamlDocSepRe.FindAllString(s, -1)
+	var out strings.Builder
+	for i, doc := range docs {
+		if secretKindRe.MatchString(doc) {
+			doc = redactYAMLSecretData(doc)
+		}
+		out.WriteString(doc)
+		if i < len(seps) {
+			out.WriteString(seps[i])
+		}
+	}
+	return out.String()
+}
+
+func redactYAMLSecretData(doc string) string {
+	lines := strings.Split(doc, "\n")
+	dataIndent := -1
+	for i, line := range lines {
+		if m := secretDataRe.FindStringSubmatch(line); m != nil {
+			dataIndent = len(m[1])
+			continue
+		}
+		if dataIndent < 0 || strings.TrimSpace(line) == "" {
+			continue
+		}
+		if indent := len(line) - len(strings.TrimLeft(line, " \t")); indent <= dataIndent {
+			dataIndent = -1
+			continue
+		}
+		if m := yamlEntryRe.FindStringSubmatch(line); m != nil {
+			lines[i] = m[1] + Placeholder
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// customRules compiles redact.patterns, recompiling only when the list
+// changes. Invalid patterns are reported once and skipped.
+func customRules() []*regexp.Regexp {
+	patterns := viper.GetStringSlice("redact.patterns")
+	customPattern.Lock()
+	defer customPattern.Unlock()
+	if slices.Equal(patterns, customPattern.source) {
+		return customPattern.rules
+	}
+	customPattern.source = append([]string(nil), patterns...)
+	customPattern.rules = nil
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: ignoring invalid redact.patterns entry %q: %v\n", p, err)
+			continue
+		}
+		customPattern.rules = append(customPattern.rules, re)
+	}
+	return customPattern.rules
+}
+
+// JSON redacts every string value in a JSON document when redaction is
+// enabled. Working on decoded values keeps the output valid JSON whatever
+// the patterns match.
+func JSON(data []byte) ([]byte, error) {
+	if !Enabled() {
+		return data, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(redactValue(doc), "", "  ")
+}
+
+func redactValue(v any) any {
+	switch t := v.(type) {
+	case string:
+		return Apply(t)
+	case []any:
+		for i := range t {
+			t[i] = redactValue(t[i])
+		}
+		return t
+	case map[string]any:
+		for k, val := range t {
+			t[k] = redactValue(val)
+		}
+		return t
+	}
+	return v
+}
@@ -0,0 +1,214 @@
+// Package reportstore keeps diagnostic and audit reports under
+// ~/.clanker/reports so a ticket filed from one can point back at the full
+// report. Each report is one file, <id>.json, holding the rendered text and
+// the structured report it was rendered from.
+package reportstore
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+)
+
+// Report kinds
+const (
+	KindK8sDiagnosis = "k8s-diagnosis"
+	KindAWSAudit     = "aws-audit"
+)
+
+// Report is a stored report
+type Report struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	Source    string    `json:"source"`
+	Question  string    `json:"question,omitempty"`
+	Summary   string    `json:"summary,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Text is the report as it was printed
+	Text string          `json:"text"`
+	Body json.RawMessage `json:"report,omitempty"`
+	// Tickets are the issues or pages filed from the report
+	Tickets []Ticket `json:"tickets,omitempty"`
+}
+
+// Ticket is an issue or page filed from a report
+type Ticket struct {
+	Tracker string    `json:"tracker"` // linear or notion
+	ID      string    `json:"id"`
+	URL     string    `json:"url,omitempty"`
+	FiledAt time.Time `json:"filedAt"`
+}
+
+// Dir returns ~/.clanker/reports
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "reports"), nil
+}
+
+// Path returns where the report with id is stored
+func Path(id string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, secfile.SafeSlug(id)+".json"), nil
+}
+
+// Add stores a report and returns it with its ID. body is the structured
+// report; nil stores the text alone.
+func Add(kind, source, question, summary, text string, body any) (*Report, error) {
+	r := &Report{
+		ID:        newID(time.Now()),
+		Kind:      kind,
+		Source:    source,
+		Question:  question,
+		Summary:   summary,
+		CreatedAt: time.Now().UTC(),
+		Text:      text,
+	}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal report: %w", err)
+		}
+		r.Body = data
+	}
+	if err := Save(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Save writes the report to ~/.clanker/reports/<id>.json
+func Save(r *Report) error {
+	if r.ID == "" {
+		return fmt.Errorf("report id is required")
+	}
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := secfile.EnsurePrivateDir(dir); err != nil {
+		return fmt.Errorf("failed to create reports directory: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if err := secfile.WritePrivate(filepath.Join(dir, secfile.SafeSlug(r.ID)+".json"), data); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
+
+// AddTicket records a ticket filed from r
+func AddTicket(r *Report, t Ticket) error {
+	if t.FiledAt.IsZero() {
+		t.FiledAt = time.Now().UTC()
+	}
+	r.Tickets = append(r.Tickets, t)
+	return Save(r)
+}
+
+// List returns every stored report, newest first. A missing store is empty.
+func List() ([]*Report, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var reports []*Report
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		r, err := read(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		if !reports[i].CreatedAt.Equal(reports[j].CreatedAt) {
+			return reports[i].CreatedAt.After(reports[j].CreatedAt)
+		}
+		return reports[i].ID > reports[j].ID
+	})
+	return reports, nil
+}
+
+// Load returns the report with the given ID; an unambiguous prefix is
+// enough
+func Load(prefix string) (*Report, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return nil, fmt.Errorf("report id is required")
+	}
+	reports, err := List()
+	if err != nil {
+		return nil, err
+	}
+	var matches []*Report
+	for _, r := range reports {
+		if r.ID == prefix {
+			return r, nil
+		}
+		if strings.HasPrefix(r.ID, prefix) {
+			matches = append(matches, r)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no report %q (see: clanker report list)", prefix)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("report id %q is ambiguous (%d matches)", prefix, len(matches))
+}
+
+// Latest returns the newest stored report, or nil when there is none
+func Latest() (*Report, error) {
+	reports, err := List()
+	if err != nil || len(reports) == 0 {
+		return nil, err
+	}
+	return reports[0], nil
+}
+
+func read(path string) (*Report, error) {
+	data, err := secfile.ReadPrivate(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", filepath.Base(path), err)
+	}
+	return &r, nil
+}
+
+func newID(now time.Time) string {
+	b := make([]byte, 2)
+	if _, err := rand.Read(b); err != nil {
+		return now.UTC().Format("20060102-150405.000000")
+	}
+	return now.UTC().Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
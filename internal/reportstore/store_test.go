@@ -0,0 +1,62 @@
+package reportstore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddListLoad(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if r, err := Latest(); err != nil || r != nil {
+		t.Fatalf("Latest() on empty store = %v, %v", r, err)
+	}
+
+	first, err := Add(KindAWSAudit, "ask aws-audit", "audit my account", "3 findings", "AWS Security Posture: 3 findings\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Add(KindK8sDiagnosis, "ask k8s", "why is deployment api failing", "deployment api has 1 issues", "Diagnostic Report\n",
+		map[string]string{"resource_name": "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.CreatedAt = first.CreatedAt.Add(1)
+	if err := Save(second); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(home, ".clanker", "reports", first.ID+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("report file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	latest, err := Latest()
+	if err != nil || latest.ID != second.ID {
+		t.Fatalf("Latest() = %v, %v; want %s", latest, err, second.ID)
+	}
+	if !strings.Contains(string(latest.Body), `"resource_name": "api"`) {
+		t.Errorf("body = %s", latest.Body)
+	}
+
+	if err := AddTicket(latest, Ticket{Tracker: "linear", ID: "OPS-12", URL: "https://linear.app/acme/issue/OPS-12"}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Tickets) != 1 || loaded.Tickets[0].ID != "OPS-12" || loaded.Tickets[0].FiledAt.IsZero() {
+		t.Errorf("tickets = %+v", loaded.Tickets)
+	}
+
+	if _, err := Load("nope"); err == nil {
+		t.Error("expected an error for an unknown id")
+	}
+}
@@ -0,0 +1,191 @@
+package routing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+)
+
+// Correction records that a question should have gone to a different agent.
+// Corrections override routing for the same question and teach the LLM
+// classifier by example.
+type Correction struct {
+	Question  string    `json:"question"`
+	Agent     string    `json:"agent"`
+	Was       string    `json:"was,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// LastRoute is the most recent question clanker ask routed and where it went
+type LastRoute struct {
+	Question string    `json:"question"`
+	Agent    string    `json:"agent"`
+	At       time.Time `json:"at"`
+}
+
+// maxFewShotExamples bounds how many corrections are added to the LLM
+// classification prompt
+const maxFewShotExamples = 10
+
+// correctionServices maps corrected agents to the services the LLM
+// classifier chooses between
+var correctionServices = map[string]string{
+	"k8s": "k8s", "iam": "iam", "aws": "aws", "gcp": "gcp", "azure": "azure",
+	"cloudflare": "cloudflare", "digitalocean": "digitalocean", "hetzner": "hetzner",
+	"oracle": "oracle", "vercel": "vercel", "flyio": "flyio", "railway": "railway",
+	"verda": "verda", "github": "github", "terraform": "terraform", "cli": "general",
+}
+
+// StoreDir returns ~/.clanker/routing
+func StoreDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "routing"), nil
+}
+
+// NormalizeQuestion is the form questions are matched in: lowercase, single
+// spaces, without trailing punctuation
+func NormalizeQuestion(question string) string {
+	q := strings.Join(strings.Fields(strings.ToLower(question)), " ")
+	return strings.TrimRight(q, "?!. ")
+}
+
+// RecordLast remembers where a question was routed, for route correct --last
+func RecordLast(question, agent string) error {
+	if strings.TrimSpace(question) == "" {
+		return nil
+	}
+	return writeStoreJSON("last.json", LastRoute{Question: question, Agent: agent, At: time.Now().UTC()})
+}
+
+// LoadLast returns the most recently routed question, or nil if there is none
+func LoadLast() (*LastRoute, error) {
+	var last LastRoute
+	found, err := readStoreJSON("last.json", &last)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &last, nil
+}
+
+// LoadCorrections returns the stored corrections, oldest first
+func LoadCorrections() ([]Correction, error) {
+	var corrections []Correction
+	if _, err := readStoreJSON("corrections.json", &corrections); err != nil {
+		return nil, err
+	}
+	return corrections, nil
+}
+
+// AddCorrection stores that question belongs to agent, replacing any earlier
+// correction for the same question
+func AddCorrection(question, agent, was string) (Correction, error) {
+	c := Correction{Question: strings.TrimSpace(question), Agent: agent, Was: was, CreatedAt: time.Now().UTC()}
+	if c.Question == "" {
+		return c, fmt.Errorf("question cannot be empty")
+	}
+	corrections, err := LoadCorrections()
+	if err != nil {
+		return c, err
+	}
+	corrections = append(withoutQuestion(corrections, c.Question), c)
+	return c, writeStoreJSON("corrections.json", corrections)
+}
+
+// RemoveCorrection deletes the correction for question and reports whether
+// there was one
+func RemoveCorrection(question string) (bool, error) {
+	corrections, err := LoadCorrections()
+	if err != nil {
+		return false, err
+	}
+	kept := withoutQuestion(corrections, question)
+	if len(kept) == len(corrections) {
+		return false, nil
+	}
+	return true, writeStoreJSON("corrections.json", kept)
+}
+
+// CorrectionFor returns the stored correction for question, if any
+func CorrectionFor(question string) (Correction, bool) {
+	corrections, err := LoadCorrections()
+	if err != nil {
+		return Correction{}, false
+	}
+	key := NormalizeQuestion(question)
+	for i := len(corrections) - 1; i >= 0; i-- {
+		if NormalizeQuestion(corrections[i].Question) == key {
+			return corrections[i], true
+		}
+	}
+	return Correction{}, false
+}
+
+// correctionExamples renders the newest corrections as few-shot examples for
+// the classification prompt
+func correctionExamples(corrections []Correction) string {
+	var lines []string
+	for i := len(corrections) - 1; i >= 0 && len(lines) < maxFewShotExamples; i-- {
+		service, ok := correctionServices[corrections[i].Agent]
+		if !ok {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- %q -> %s", corrections[i].Question, service))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "The user corrected these classifications before; classify similar queries the same way:\n" + strings.Join(lines, "\n") + "\n\n"
+}
+
+func withoutQuestion(corrections []Correction, question string) []Correction {
+	key := NormalizeQuestion(question)
+	kept := make([]Correction, 0, len(corrections))
+	for _, c := range corrections {
+		if NormalizeQuestion(c.Question) != key {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+func readStoreJSON(name string, v any) (bool, error) {
+	dir, err := StoreDir()
+	if err != nil {
+		return false, err
+	}
+	data, err := secfile.ReadPrivate(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func writeStoreJSON(name string, v any) error {
+	dir, err := StoreDir()
+	if err != nil {
+		return err
+	}
+	if err := secfile.EnsurePrivateDir(dir); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return secfile.WritePrivate(filepath.Join(dir, name), data)
+}
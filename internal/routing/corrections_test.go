@@ -0,0 +1,88 @@
+package routing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCorrectionsRoundTrip(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if _, ok := CorrectionFor("anything"); ok {
+		t.Fatal("empty store should have no corrections")
+	}
+	if last, err := LoadLast(); err != nil || last != nil {
+		t.Fatalf("LoadLast() on empty store = %v, %v", last, err)
+	}
+
+	if err := RecordLast("What is eating memory on web-1?", "cli"); err != nil {
+		t.Fatal(err)
+	}
+	last, err := LoadLast()
+	if err != nil || last == nil || last.Agent != "cli" {
+		t.Fatalf("LoadLast() = %+v, %v", last, err)
+	}
+
+	if _, err := AddCorrection(last.Question, "agent-observability", last.Agent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AddCorrection("what is eating  memory on WEB-1", "k8s", "agent-observability"); err != nil {
+		t.Fatal(err)
+	}
+
+	corrections, err := LoadCorrections()
+	if err != nil || len(corrections) != 1 {
+		t.Fatalf("a second correction for the same question should replace the first, got %+v, %v", corrections, err)
+	}
+	c, ok := CorrectionFor("what is eating memory on web-1")
+	if !ok || c.Agent != "k8s" {
+		t.Errorf("CorrectionFor = %+v, %v", c, ok)
+	}
+
+	info, err := os.Stat(filepath.Join(home, ".clanker", "routing", "corrections.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("corrections file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if removed, err := RemoveCorrection("What is eating memory on web-1"); err != nil || !removed {
+		t.Errorf("RemoveCorrection = %v, %v", removed, err)
+	}
+	if removed, _ := RemoveCorrection("What is eating memory on web-1"); removed {
+		t.Error("second RemoveCorrection should find nothing")
+	}
+}
+
+func TestClassificationPromptIncludesCorrections(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if strings.Contains(GetClassificationPrompt("q"), "corrected") {
+		t.Fatal("prompt should not mention corrections when there are none")
+	}
+
+	for _, c := range []struct{ question, agent string }{
+		{"show the edge cache hit rate", "cloudflare"},
+		{"who can read the billing bucket", "iam"},
+		{"largest tables", "agent-database"},
+	} {
+		if _, err := AddCorrection(c.question, c.agent, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prompt := GetClassificationPrompt("q")
+	for _, want := range []string{`"show the edge cache hit rate" -> cloudflare`, `"who can read the billing bucket" -> iam`} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q", want)
+		}
+	}
+	if strings.Contains(prompt, "largest tables") {
+		t.Error("agents the classifier cannot choose should not become examples")
+	}
+	if strings.Index(prompt, "-> cloudflare") > strings.Index(prompt, "Respond with ONLY") {
+		t.Error("examples should come before the response format")
+	}
+}
@@ -18,6 +18,7 @@ type ServiceContext struct {
 	AWS          bool
 	GitHub       bool
 	Terraform    bool
+	Pulumi       bool
 	K8s          bool
 	GCP          bool
 	Azure        bool
@@ -33,6 +34,26 @@ type ServiceContext struct {
 	Code         bool
 }
 
+// Services lists the detected services by name
+func (c ServiceContext) Services() []string {
+	var services []string
+	for _, s := range []struct {
+		name string
+		on   bool
+	}{
+		{"aws", c.AWS}, {"github", c.GitHub}, {"terraform", c.Terraform}, {"pulumi", c.Pulumi}, {"k8s", c.K8s},
+		{"gcp", c.GCP}, {"azure", c.Azure}, {"cloudflare", c.Cloudflare},
+		{"digitalocean", c.DigitalOcean}, {"hetzner", c.Hetzner}, {"oracle", c.Oracle},
+		{"vercel", c.Vercel}, {"flyio", c.Flyio}, {"railway", c.Railway}, {"verda", c.Verda},
+		{"iam", c.IAM}, {"code", c.Code},
+	} {
+		if s.on {
+			services = append(services, s.name)
+		}
+	}
+	return services
+}
+
 // Classification represents the result of LLM-based query classification
 type Classification struct {
 	Service    string `json:"service"`
@@ -56,6 +77,7 @@ func applyConfiguredDefaultContext(ctx *ServiceContext) {
 	ctx.AWS = false
 	ctx.GitHub = false
 	ctx.Terraform = false
+	ctx.Pulumi = false
 	ctx.K8s = false
 	ctx.GCP = false
 	ctx.Azure = false
@@ -168,7 +190,7 @@ func InferContext(question string) ServiceContext {
 		"workspace", "state", "backend", "provider", "resource", "data",
 		"module", "variable", "output", "local",
 		// Terraform alternatives
-		"pulumi", "crossplane", "cloudformation", "aws cdk", "cdktf", "bicep", "infrastructure manager",
+		"crossplane", "cloudformation", "aws cdk", "cdktf", "bicep", "infrastructure manager",
 		// Operations
 		"infrastructure-as-code", "iac", "provisioning", "deployment",
 		"environment", "stack", "configuration", "template",
@@ -454,6 +476,7 @@ func InferContext(question string) ServiceContext {
 		"least privilege", "security audit", "iam analysis",
 		"overpermissive", "admin access", "cross-account trust",
 		"mfa status", "unused role", "wildcard permission",
+		"access analyzer", "external access", "external exposure",
 	}
 
 	questionLower := strings.ToLower(question)
@@ -488,6 +511,10 @@ func InferContext(question string) ServiceContext {
 		}
 	}
 
+	// Pulumi has no generic vocabulary of its own; stack, preview and up
+	// all mean other things elsewhere
+	ctx.Pulumi = contains(questionLower, "pulumi")
+
 	for _, keyword := range k8sKeywords {
 		if contains(questionLower, keyword) {
 			ctx.K8s = true
@@ -572,7 +599,7 @@ func InferContext(question string) ServiceContext {
 
 	// Default to the configured provider if nothing is detected.
 	// AWS keeps GitHub enabled for backward compatibility.
-	if !ctx.AWS && !ctx.GitHub && !ctx.Terraform && !ctx.K8s && !ctx.GCP && !ctx.Azure && !ctx.Cloudflare && !ctx.DigitalOcean && !ctx.Hetzner && !ctx.Oracle && !ctx.Vercel && !ctx.Flyio && !ctx.Verda && !ctx.IAM {
+	if !ctx.AWS && !ctx.GitHub && !ctx.Terraform && !ctx.Pulumi && !ctx.K8s && !ctx.GCP && !ctx.Azure && !ctx.Cloudflare && !ctx.DigitalOcean && !ctx.Hetzner && !ctx.Oracle && !ctx.Vercel && !ctx.Flyio && !ctx.Verda && !ctx.IAM {
 		applyConfiguredDefaultContext(&ctx)
 	}
 
@@ -582,6 +609,8 @@ func InferContext(question string) ServiceContext {
 // GetClassificationPrompt returns a prompt for LLM to classify which service a query is about
 func GetClassificationPrompt(question string) string {
 	defaultProvider := DefaultInfraProvider()
+	// Corrections recorded with clanker route correct serve as examples
+	corrections, _ := LoadCorrections()
 	return fmt.Sprintf(`Classify which cloud service or platform this user query is about.
 
 User Query: "%s"
@@ -617,12 +646,12 @@ IMPORTANT RULES:
 11. Only classify as "verda" if the query EXPLICITLY mentions Verda, DataCrunch, Verda clusters/instances, or an Instant Cluster (Verda's managed cluster product)
 12. If uncertain, classify as "%s" (the configured default cloud provider)
 
-Respond with ONLY a JSON object:
+%sRespond with ONLY a JSON object:
 {
 	"service": "cloudflare|aws|iam|k8s|gcp|azure|digitalocean|hetzner|oracle|vercel|flyio|railway|verda|github|terraform|general",
     "confidence": "high|medium|low",
     "reason": "brief explanation of why this classification"
-}`, question, defaultProvider, defaultProvider)
+}`, question, defaultProvider, defaultProvider, correctionExamples(corrections))
 }
 
 // ClassifyWithLLM uses the AI client to determine which service a query is about.
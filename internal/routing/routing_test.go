@@ -530,3 +530,18 @@ func TestApplyLLMClassification_Verda(t *testing.T) {
 		t.Error("other cloud providers should be cleared when LLM picks verda")
 	}
 }
+
+func TestInferContext_Pulumi(t *testing.T) {
+	useDefaultProvider(t, "")
+
+	ctx := InferContext("what would a pulumi preview change for shop")
+	if !ctx.Pulumi {
+		t.Error("expected Pulumi=true for an explicit pulumi question")
+	}
+	if ctx.Terraform {
+		t.Error("pulumi alone should not select Terraform context")
+	}
+	if InferContext("show terraform outputs for prod").Pulumi {
+		t.Error("expected Pulumi=false for a terraform question")
+	}
+}
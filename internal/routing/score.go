@@ -0,0 +1,173 @@
+package routing
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Rule is one routing signal. When Match finds evidence in a question, the
+// rule's agent becomes a candidate scored by Weight.
+type Rule struct {
+	Agent  string
+	Weight float64
+	Reason string
+	// Match returns the evidence found in the lowercased question, or nil
+	Match func(questionLower string) []string
+	// FanOut marks read-only agents that can answer alongside other agents
+	// when a question asks about several systems at once
+	FanOut bool
+}
+
+// Candidate is an agent ranked for a question
+type Candidate struct {
+	Agent      string   `json:"agent"`
+	Confidence float64  `json:"confidence"`
+	Reason     string   `json:"reason"`
+	Signals    []string `json:"signals,omitempty"`
+
+	score  float64
+	fanOut bool
+}
+
+// Decision is the ranked result of scoring a question
+type Decision struct {
+	Agent      string      `json:"agent"`
+	Confidence float64     `json:"confidence"`
+	Reason     string      `json:"reason"`
+	Pinned     bool        `json:"pinned,omitempty"`
+	FanOut     []string    `json:"fanOut,omitempty"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// fallbackWeight is the score of the fallback agent, which is always a
+// candidate so a single weak match never reads as certain
+const fallbackWeight = 1
+
+// maxSignalBonus caps how much repeated evidence adds to a rule's weight.
+// Rules are spaced further apart than this so more evidence never lets a
+// lower-priority rule overtake a higher one.
+const maxSignalBonus = 4
+
+// fanOutCues are phrases that ask about several systems in one question
+var fanOutCues = []string{"both", " and ", "as well as", "along with", "together with", "compare", "across"}
+
+// Keywords returns a matcher reporting which of words occur in a question
+func Keywords(words ...string) func(string) []string {
+	return func(questionLower string) []string {
+		var found []string
+		for _, w := range words {
+			if strings.Contains(questionLower, w) {
+				found = append(found, strings.TrimSpace(w))
+			}
+		}
+		return found
+	}
+}
+
+// Score ranks the agents whose rules match question. The fallback agent is
+// always ranked, last unless nothing else matched. Confidence is each
+// candidate's share of the total score. When the top agent can fan out and
+// the question asks about several systems, every matching FanOut agent is
+// listed to answer it together.
+func Score(question string, rules []Rule, fallbackAgent, fallbackReason string) Decision {
+	questionLower := strings.ToLower(question)
+
+	byAgent := make(map[string]*Candidate)
+	var order []string
+	for _, rule := range rules {
+		signals := rule.Match(questionLower)
+		if len(signals) == 0 {
+			continue
+		}
+		score := rule.Weight + math.Min(float64(len(signals)-1), maxSignalBonus)
+		c, ok := byAgent[rule.Agent]
+		if !ok {
+			c = &Candidate{Agent: rule.Agent}
+			byAgent[rule.Agent] = c
+			order = append(order, rule.Agent)
+		}
+		if score > c.score {
+			c.score, c.Reason = score, rule.Reason
+		}
+		c.Signals = appendUnique(c.Signals, signals...)
+		c.fanOut = c.fanOut || rule.FanOut
+	}
+	if _, ok := byAgent[fallbackAgent]; !ok {
+		byAgent[fallbackAgent] = &Candidate{Agent: fallbackAgent, Reason: fallbackReason, score: fallbackWeight}
+		order = append(order, fallbackAgent)
+	}
+
+	candidates := make([]Candidate, 0, len(order))
+	var total float64
+	for _, agent := range order {
+		candidates = append(candidates, *byAgent[agent])
+		total += byAgent[agent].score
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	for i := range candidates {
+		candidates[i].Confidence = roundConfidence(candidates[i].score / total)
+	}
+
+	decision := Decision{
+		Agent:      candidates[0].Agent,
+		Confidence: candidates[0].Confidence,
+		Reason:     candidates[0].Reason,
+		Candidates: candidates,
+	}
+	if candidates[0].fanOut && hasFanOutCue(questionLower) {
+		var fanOut []string
+		for _, c := range candidates {
+			if c.fanOut {
+				fanOut = append(fanOut, c.Agent)
+			}
+		}
+		if len(fanOut) > 1 {
+			decision.FanOut = fanOut
+		}
+	}
+	return decision
+}
+
+// Pin makes agent the decision regardless of its score. The scored
+// candidates are kept so the output still shows what routing would have
+// picked.
+func (d *Decision) Pin(agent, reason string) {
+	d.Agent, d.Confidence, d.Reason, d.Pinned, d.FanOut = agent, 1, reason, true, nil
+}
+
+// ForcedAgent returns the agent pinned with routing.force in the config, or ""
+func ForcedAgent() string {
+	return strings.ToLower(strings.TrimSpace(viper.GetString("routing.force")))
+}
+
+func hasFanOutCue(questionLower string) bool {
+	for _, cue := range fanOutCues {
+		if strings.Contains(questionLower, cue) {
+			return true
+		}
+	}
+	return false
+}
+
+func roundConfidence(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, existing := range list {
+			if existing == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,89 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func testRules() []Rule {
+	return []Rule{
+		{Agent: "iam", Weight: 90, Reason: "IAM", FanOut: true, Match: Keywords("iam role", "trust policy")},
+		{Agent: "maker", Weight: 60, Reason: "Provisioning", Match: Keywords("create")},
+		{Agent: "k8s", Weight: 50, Reason: "K8s", FanOut: true, Match: Keywords("pod", "deployment", "namespace", "node", "helm")},
+	}
+}
+
+func TestScoreRanksByWeight(t *testing.T) {
+	d := Score("create a pod", testRules(), "cli", "General")
+	if d.Agent != "maker" || d.Reason != "Provisioning" {
+		t.Fatalf("decision = %+v", d)
+	}
+	var agents []string
+	for _, c := range d.Candidates {
+		agents = append(agents, c.Agent)
+	}
+	if !reflect.DeepEqual(agents, []string{"maker", "k8s", "cli"}) {
+		t.Errorf("candidates = %v", agents)
+	}
+	if d.Confidence <= d.Candidates[1].Confidence || d.Confidence >= 1 {
+		t.Errorf("confidence = %v, runner-up %v", d.Confidence, d.Candidates[1].Confidence)
+	}
+}
+
+func TestScoreSignalBonusNeverOvertakesPriority(t *testing.T) {
+	d := Score("create pod deployment namespace node helm", testRules(), "cli", "General")
+	if d.Agent != "maker" {
+		t.Errorf("agent = %q, want maker", d.Agent)
+	}
+	if got := d.Candidates[1].Signals; len(got) != 5 {
+		t.Errorf("k8s signals = %v", got)
+	}
+}
+
+func TestScoreFallback(t *testing.T) {
+	d := Score("how many lambdas", testRules(), "cli", "General")
+	if d.Agent != "cli" || d.Confidence != 1 || len(d.Candidates) != 1 {
+		t.Errorf("decision = %+v", d)
+	}
+}
+
+func TestScoreFanOut(t *testing.T) {
+	d := Score("check the iam role and the pods in prod", testRules(), "cli", "General")
+	if !reflect.DeepEqual(d.FanOut, []string{"iam", "k8s"}) {
+		t.Errorf("fan-out = %v", d.FanOut)
+	}
+
+	if d := Score("check the iam role for pods", testRules(), "cli", "General"); d.FanOut != nil {
+		t.Errorf("no cue, fan-out = %v", d.FanOut)
+	}
+	if d := Score("create a pod and a namespace", testRules(), "cli", "General"); d.FanOut != nil {
+		t.Errorf("provisioning top agent should not fan out, got %v", d.FanOut)
+	}
+}
+
+func TestPinAndForcedAgent(t *testing.T) {
+	previous := viper.GetString("routing.force")
+	viper.Set("routing.force", " K8s ")
+	t.Cleanup(func() { viper.Set("routing.force", previous) })
+
+	if got := ForcedAgent(); got != "k8s" {
+		t.Fatalf("ForcedAgent() = %q", got)
+	}
+	d := Score("check the iam role and the pods", testRules(), "cli", "General")
+	d.Pin(ForcedAgent(), "pinned")
+	if d.Agent != "k8s" || d.Confidence != 1 || !d.Pinned || d.FanOut != nil {
+		t.Errorf("pinned decision = %+v", d)
+	}
+	if d.Candidates[0].Agent != "iam" {
+		t.Errorf("pinning should keep the scored candidates, got %v", d.Candidates)
+	}
+}
+
+func TestServiceContextServices(t *testing.T) {
+	got := ServiceContext{AWS: true, GitHub: true, Cloudflare: true}.Services()
+	if !reflect.DeepEqual(got, []string{"aws", "github", "cloudflare"}) {
+		t.Errorf("Services() = %v", got)
+	}
+}
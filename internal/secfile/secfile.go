@@ -5,8 +5,8 @@
 // world-readable Q&A, plans, tokens, or cloud metadata.
 //
 // This is a security primitive (file modes + Chmod-repair), not a
-// conversation-history abstraction. The larger refactor — extracting
-// shared Load/Save logic across providers — is tracked in #25.
+// conversation-history abstraction; the Load/Save logic shared across
+// providers lives in internal/convhistory.
 package secfile
 
 import (
@@ -0,0 +1,147 @@
+package secrets
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/redact"
+)
+
+// staleAfter is how long a secret can go without a new value before a
+// listing calls it out
+const staleAfter = 90 * 24 * time.Hour
+
+// Format renders the secrets of each store as a table of their metadata,
+// followed by the secrets that have not changed for staleAfter and have no
+// rotation schedule. The output is always redacted.
+func (inv *Inventory) Format(now time.Time) string {
+	var sb strings.Builder
+	var stale []string
+	for _, store := range inv.Stores {
+		var secrets []Secret
+		for _, s := range inv.Secrets {
+			if s.Store == store {
+				secrets = append(secrets, s)
+			}
+		}
+		if err, failed := inv.Errors[store]; failed {
+			fmt.Fprintf(&sb, "%s: not listed (%v)\n\n", store.Label(), err)
+			continue
+		}
+		if len(secrets) == 0 {
+			fmt.Fprintf(&sb, "%s: none\n\n", store.Label())
+			continue
+		}
+
+		fmt.Fprintf(&sb, "%s (%d):\n", store.Label(), len(secrets))
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  SECRET\tCREATED\tCHANGED\tLAST ACCESSED\tROTATION\tDETAILS")
+		for _, s := range secrets {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", s.Location(), day(s.Created), day(s.Updated), day(s.LastAccessed), rotation(s), orDash(details(s)))
+			if !s.Updated.IsZero() && now.Sub(s.Updated) > staleAfter && s.RotationPeriod == "" && s.RotationLambda == "" {
+				stale = append(stale, fmt.Sprintf("  %s %s (%d days)", store.Label(), s.Location(), int(now.Sub(s.Updated).Hours()/24)))
+			}
+		}
+		tw.Flush()
+		sb.WriteString("\n")
+	}
+	if len(inv.Stores) == 0 {
+		sb.WriteString("No secret stores are configured.\n")
+	}
+	if len(stale) > 0 {
+		fmt.Fprintf(&sb, "Not changed in %d days and not rotated automatically:\n%s\n", int(staleAfter.Hours()/24), strings.Join(stale, "\n"))
+	}
+	return redact.Apply(strings.TrimRight(sb.String(), "\n") + "\n")
+}
+
+// rotation describes the secret's rotation schedule
+func rotation(s Secret) string {
+	switch s.Store {
+	case SecretsManager, GCPSecretManager:
+		if s.RotationPeriod == "" && s.RotationLambda == "" {
+			return "off"
+		}
+		out := "on"
+		if s.RotationPeriod != "" {
+			out = "every " + s.RotationPeriod
+		}
+		if !s.NextRotation.IsZero() {
+			out += ", next " + day(s.NextRotation)
+		}
+		return out
+	}
+	return "-"
+}
+
+// details are the store-specific facts worth a column: the type and keys
+// of a Kubernetes secret and the key a cloud secret is encrypted with
+func details(s Secret) string {
+	var parts []string
+	if s.Type != "" && s.Type != "Opaque" {
+		parts = append(parts, s.Type)
+	}
+	if len(s.Keys) > 0 {
+		parts = append(parts, "keys: "+strings.Join(s.Keys, ","))
+	}
+	if s.KMSKey != "" {
+		parts = append(parts, "kms: "+s.KMSKey)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Format lists the workloads that read the secret, then the sources that
+// could not be scanned. The output is always redacted.
+func (r *ReferenceReport) Format() string {
+	var sb strings.Builder
+	if len(r.References) == 0 {
+		fmt.Fprintf(&sb, "No workloads found that read %s.\n", r.Name)
+	} else {
+		fmt.Fprintf(&sb, "Workloads that read %s (%d):\n", r.Name, len(r.References))
+		tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  KIND\tWORKLOAD\tVIA")
+		for _, ref := range r.References {
+			name := ref.Name
+			if ref.Namespace != "" {
+				name = ref.Namespace + "/" + ref.Name
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", ref.Kind, name, ref.Via)
+		}
+		tw.Flush()
+	}
+	for _, source := range []string{sourceK8s, sourceExternal, sourceECS, sourceLambda, sourceCloudRun} {
+		if err, failed := r.Errors[source]; failed {
+			fmt.Fprintf(&sb, "Not scanned: %s (%v)\n", source, err)
+		}
+	}
+	return redact.Apply(sb.String())
+}
+
+// Format renders a Kubernetes change as the steps to run and its notes.
+// The output is always redacted.
+func (c *Change) Format() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s\n\nRun:\n", c.Summary)
+	for _, step := range c.Steps {
+		fmt.Fprintf(&sb, "  %s\n", step)
+	}
+	for _, note := range c.Notes {
+		fmt.Fprintf(&sb, "\n%s", note)
+	}
+	return redact.Apply(strings.TrimRight(sb.String(), "\n") + "\n")
+}
+
+func day(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
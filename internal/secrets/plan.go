@@ -0,0 +1,289 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// Placeholders the plans leave for the apply step to fill from the
+// environment, so a new value is never written to a plan
+const (
+	// ValueVariable holds the new value of an AWS secret or parameter
+	ValueVariable = "NEW_SECRET_VALUE"
+	// FileVariable holds the path of a file with the new value of a GCP
+	// secret
+	FileVariable = "NEW_SECRET_FILE"
+)
+
+// Change is a rotation or a new secret. AWS and GCP changes are maker
+// plans. Kubernetes changes are kubectl steps to run by hand, since a
+// secret is replaced by piping one kubectl command into another.
+type Change struct {
+	Summary string
+	Plan    *maker.Plan
+	// Steps are the kubectl command lines of a Kubernetes change
+	Steps []string
+	Notes []string
+}
+
+// Plan builds the change for a rotate or create request. Nothing is
+// changed; AWS and GCP plans are applied with clanker ask --apply.
+func (a *Agent) Plan(ctx context.Context, req Request, question string, now time.Time) (*Change, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("name the secret to %s", opVerb(req.Op))
+	}
+	switch req.Op {
+	case Rotate:
+		inv := a.List(ctx, req.Stores, req.Namespace)
+		secret, err := findOne(inv, req.Name)
+		if err != nil {
+			return nil, err
+		}
+		name := secret.Name
+		if secret.Store == Kubernetes {
+			name = secret.Location()
+		}
+		refs := a.References(ctx, name, secret.Namespace)
+		if a.debug {
+			for source, err := range refs.Errors {
+				fmt.Printf("[secrets] could not scan %s: %v\n", source, err)
+			}
+		}
+		return BuildRotation(secret, refs.References, question, now), nil
+	case Create:
+		store, err := createStore(req.Stores)
+		if err != nil {
+			return nil, err
+		}
+		inv := a.List(ctx, []Store{store}, req.Namespace)
+		if err := inv.Errors[store]; err != nil {
+			return nil, err
+		}
+		for _, s := range inv.Find(req.Name) {
+			if s.Store == store && (store != Kubernetes || req.Namespace == "" || s.Namespace == req.Namespace) {
+				return nil, fmt.Errorf("%s already has a secret named %s; ask to rotate it instead", store.Label(), s.Location())
+			}
+		}
+		return BuildCreation(store, req.Name, req.Namespace, question, now), nil
+	}
+	return nil, fmt.Errorf("nothing to plan for a listing")
+}
+
+func opVerb(op Operation) string {
+	if op == Create {
+		return "create"
+	}
+	return "rotate"
+}
+
+// findOne returns the only secret in inv called name
+func findOne(inv *Inventory, name string) (Secret, error) {
+	found := inv.Find(name)
+	switch len(found) {
+	case 1:
+		return found[0], nil
+	case 0:
+		msg := fmt.Sprintf("no secret named %s found", name)
+		var failed []string
+		for s, err := range inv.Errors {
+			failed = append(failed, fmt.Sprintf("%s: %v", s.Label(), err))
+		}
+		sort.Strings(failed)
+		if len(failed) > 0 {
+			msg += " (could not list " + strings.Join(failed, "; ") + ")"
+		}
+		return Secret{}, fmt.Errorf("%s", msg)
+	}
+	var where []string
+	for _, s := range found {
+		where = append(where, s.Store.Label()+" "+s.Location())
+	}
+	return Secret{}, fmt.Errorf("%s is in several stores (%s); say which one", name, strings.Join(where, ", "))
+}
+
+// createStore is the store a new secret goes to; AWS on its own means
+// Secrets Manager
+func createStore(stores []Store) (Store, error) {
+	switch {
+	case len(stores) == 1:
+		return stores[0], nil
+	case len(stores) == 2 && stores[0] == SecretsManager && stores[1] == ParameterStore:
+		return SecretsManager, nil
+	case containsStore(stores, Kubernetes):
+		return Kubernetes, nil
+	}
+	return "", fmt.Errorf("say where to create the secret: Secrets Manager, Parameter Store, GCP or Kubernetes")
+}
+
+func newPlan(question, summary string, now time.Time) *maker.Plan {
+	return &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "aws",
+		Question:  question,
+		Summary:   summary,
+	}
+}
+
+// valueNote tells the user how to hand the new value to the apply step
+func valueNote(store Store) string {
+	if store == GCPSecretManager {
+		return fmt.Sprintf("Set %s to the path of a file holding the new value before applying; the file is read by gcloud and never copied into the plan.", FileVariable)
+	}
+	return fmt.Sprintf("Export %s with the new value before applying; it is substituted when the command runs and never written to the plan.", ValueVariable)
+}
+
+// BuildRotation builds the change that gives s a new value, with notes on
+// the workloads in refs that have to pick it up
+func BuildRotation(s Secret, refs []Reference, question string, now time.Time) *Change {
+	change := &Change{}
+	switch s.Store {
+	case SecretsManager:
+		change.Summary = fmt.Sprintf("Rotate Secrets Manager secret %s", s.Name)
+		if s.RotationLambda != "" {
+			change.Summary += " with its rotation function"
+		}
+		change.Plan = newPlan(question, change.Summary, now)
+		if s.RotationLambda != "" {
+			change.Plan.Commands = []maker.Command{{
+				Args:   []string{"secretsmanager", "rotate-secret", "--secret-id", s.ID},
+				Reason: fmt.Sprintf("Run %s now to create, set and test a new value", s.RotationLambda),
+			}}
+		} else {
+			change.Plan.Commands = []maker.Command{{
+				Args:   []string{"secretsmanager", "put-secret-value", "--secret-id", s.ID, "--secret-string", "<" + ValueVariable + ">"},
+				Reason: "Store the new value as the AWSCURRENT version; the old value stays available as AWSPREVIOUS",
+			}}
+			change.Notes = append(change.Notes, valueNote(s.Store),
+				fmt.Sprintf("%s has no rotation function, so the new value must already be accepted by whatever the secret unlocks.", s.Name))
+		}
+	case ParameterStore:
+		change.Summary = fmt.Sprintf("Rotate SecureString parameter %s", s.Name)
+		change.Plan = newPlan(question, change.Summary, now)
+		args := []string{"ssm", "put-parameter", "--name", s.Name, "--type", "SecureString", "--overwrite", "--value", "<" + ValueVariable + ">"}
+		if s.KMSKey != "" {
+			args = append(args, "--key-id", s.KMSKey)
+		}
+		change.Plan.Commands = []maker.Command{{Args: args, Reason: "Write the new value as the next parameter version"}}
+		change.Notes = append(change.Notes, valueNote(s.Store))
+	case GCPSecretManager:
+		change.Summary = fmt.Sprintf("Rotate GCP secret %s", s.Name)
+		change.Plan = newPlan(question, change.Summary, now)
+		change.Plan.Provider = "gcp"
+		change.Plan.Commands = []maker.Command{{
+			Args:   []string{"secrets", "versions", "add", s.Name, "--data-file=<" + FileVariable + ">"},
+			Reason: "Add a version with the new value; it becomes the latest version consumers resolve",
+		}}
+		change.Notes = append(change.Notes, valueNote(s.Store),
+			fmt.Sprintf("Once every consumer reads the new version, disable the old one with: gcloud secrets versions disable <version> --secret %s", s.Name))
+	case Kubernetes:
+		change.Summary = fmt.Sprintf("Rotate Kubernetes secret %s", s.Location())
+		change.Steps = []string{k8sSecretCommand(s.Name, s.Namespace, s.Type, s.Keys) + " --dry-run=client -o yaml | kubectl apply -f -"}
+		for _, r := range refs {
+			if r.Restartable() && r.Namespace == s.Namespace {
+				step := fmt.Sprintf("kubectl rollout restart %s/%s -n %s", strings.ToLower(r.Kind), r.Name, r.Namespace)
+				if !containsString(change.Steps, step) {
+					change.Steps = append(change.Steps, step)
+				}
+			}
+		}
+		change.Notes = append(change.Notes, "Write each key's new value to the file named after it, and delete the files once applied.")
+	}
+	change.Notes = append(change.Notes, referencesNote(s, refs))
+	if change.Plan != nil {
+		change.Plan.Notes = change.Notes
+	}
+	return change
+}
+
+// BuildCreation builds the change that creates a secret called name in
+// store
+func BuildCreation(store Store, name, namespace, question string, now time.Time) *Change {
+	change := &Change{Summary: fmt.Sprintf("Create %s secret %s", store.Label(), name)}
+	switch store {
+	case SecretsManager:
+		change.Plan = newPlan(question, change.Summary, now)
+		change.Plan.Commands = []maker.Command{{
+			Args:   []string{"secretsmanager", "create-secret", "--name", name, "--secret-string", "<" + ValueVariable + ">"},
+			Reason: "Create the secret with its first value",
+		}}
+		change.Notes = append(change.Notes, valueNote(store))
+	case ParameterStore:
+		change.Summary = fmt.Sprintf("Create SecureString parameter %s", name)
+		change.Plan = newPlan(question, change.Summary, now)
+		change.Plan.Commands = []maker.Command{{
+			Args:   []string{"ssm", "put-parameter", "--name", name, "--type", "SecureString", "--value", "<" + ValueVariable + ">"},
+			Reason: "Create the parameter encrypted with the account's default SSM key",
+		}}
+		change.Notes = append(change.Notes, valueNote(store))
+	case GCPSecretManager:
+		change.Plan = newPlan(question, change.Summary, now)
+		change.Plan.Provider = "gcp"
+		change.Plan.Commands = []maker.Command{{
+			Args:   []string{"secrets", "create", name, "--replication-policy=automatic", "--data-file=<" + FileVariable + ">"},
+			Reason: "Create the secret with its first version",
+		}}
+		change.Notes = append(change.Notes, valueNote(store))
+	case Kubernetes:
+		if namespace == "" {
+			namespace = "default"
+		}
+		change.Summary = fmt.Sprintf("Create Kubernetes secret %s/%s", namespace, name)
+		change.Steps = []string{k8sSecretCommand(name, namespace, "", nil)}
+		change.Notes = append(change.Notes, "Write the value to a file named after its key, and delete the file once applied.")
+	}
+	if change.Plan != nil {
+		change.Plan.Notes = change.Notes
+	}
+	return change
+}
+
+// k8sSecretCommand is the kubectl create command for a secret with keys,
+// reading each key from a file of the same name so no value reaches the
+// shell history
+func k8sSecretCommand(name, namespace, secretType string, keys []string) string {
+	args := []string{"kubectl", "create", "secret", "generic", name, "-n", namespace}
+	if secretType != "" && secretType != "Opaque" {
+		args = append(args, "--type="+secretType)
+	}
+	if len(keys) == 0 {
+		keys = []string{"<key>"}
+	}
+	for _, k := range keys {
+		args = append(args, fmt.Sprintf("--from-file=%s=./%s", k, k))
+	}
+	return strings.Join(args, " ")
+}
+
+// referencesNote lists the workloads that read s and have to be restarted
+// or redeployed to pick up a new value
+func referencesNote(s Secret, refs []Reference) string {
+	var names []string
+	for _, r := range refs {
+		label := r.Kind + " " + r.Name
+		if r.Namespace != "" {
+			label = r.Kind + " " + r.Namespace + "/" + r.Name
+		}
+		if !containsString(names, label) {
+			names = append(names, label)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("No workloads were found reading %s; check anything outside the scanned Kubernetes, ECS, Lambda and Cloud Run workloads.", s.Name)
+	}
+	return fmt.Sprintf("Workloads that read %s and may need a restart or redeploy to pick up the new value: %s", s.Name, strings.Join(names, ", "))
+}
+
+func containsString(list []string, s string) bool {
+	for _, have := range list {
+		if have == s {
+			return true
+		}
+	}
+	return false
+}
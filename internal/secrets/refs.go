@@ -0,0 +1,435 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Reference is a workload that reads a secret
+type Reference struct {
+	// Kind is the workload's kind, such as Deployment or ECS task definition
+	Kind      string
+	Name      string
+	Namespace string
+	// Via says how the workload reads the secret, such as "env DB_PASSWORD
+	// in container app"
+	Via string
+}
+
+// Restartable reports whether the workload keeps running with the value it
+// read at startup, so it needs a rollout restart to pick up a rotation
+func (r Reference) Restartable() bool {
+	switch r.Kind {
+	case "Deployment", "StatefulSet", "DaemonSet":
+		return true
+	}
+	return false
+}
+
+// ReferenceReport is every workload found to read a secret
+type ReferenceReport struct {
+	Name string
+	// Namespace limits Kubernetes references to one namespace
+	Namespace  string
+	References []Reference
+	// Errors holds the sources that could not be scanned, such as "ECS task
+	// definitions"
+	Errors map[string]error
+}
+
+// Sources scanned for references
+const (
+	sourceK8s       = "Kubernetes workloads"
+	sourceExternal  = "ExternalSecrets"
+	sourceECS       = "ECS task definitions"
+	sourceLambda    = "Lambda functions"
+	sourceCloudRun  = "Cloud Run services"
+	missingResource = "the server doesn't have a resource type"
+)
+
+// References scans Kubernetes workloads and ExternalSecrets, ECS task
+// definitions, Lambda environments and Cloud Run services for the secret
+// called name. Only the names of the variables and volumes that carry it
+// are kept, never their values.
+func (a *Agent) References(ctx context.Context, name, namespace string) *ReferenceReport {
+	report := &ReferenceReport{Name: name, Namespace: namespace, Errors: map[string]error{}}
+	scan := func(source string, refs []Reference, err error) {
+		if err != nil {
+			report.Errors[source] = err
+			return
+		}
+		report.References = append(report.References, refs...)
+	}
+	if a.runners.Kubectl != nil {
+		refs, err := a.k8sReferences(ctx, name, namespace)
+		scan(sourceK8s, refs, err)
+		refs, err = a.externalSecretReferences(ctx, name)
+		if err != nil && strings.Contains(err.Error(), missingResource) {
+			err = nil
+		}
+		scan(sourceExternal, refs, err)
+	}
+	if a.runners.AWS != nil {
+		refs, err := a.ecsReferences(ctx, name)
+		scan(sourceECS, refs, err)
+		refs, err = a.lambdaReferences(ctx, name)
+		scan(sourceLambda, refs, err)
+	}
+	if a.runners.GCloud != nil {
+		refs, err := a.cloudRunReferences(ctx, name)
+		scan(sourceCloudRun, refs, err)
+	}
+	sort.SliceStable(report.References, func(i, j int) bool {
+		ri, rj := report.References[i], report.References[j]
+		if ri.Kind != rj.Kind {
+			return ri.Kind < rj.Kind
+		}
+		return ri.Namespace+"/"+ri.Name < rj.Namespace+"/"+rj.Name
+	})
+	return report
+}
+
+// podSpec is the part of a pod template that can reference secrets
+type podSpec struct {
+	Containers       []k8sContainer `json:"containers"`
+	InitContainers   []k8sContainer `json:"initContainers"`
+	ImagePullSecrets []struct {
+		Name string `json:"name"`
+	} `json:"imagePullSecrets"`
+	Volumes []struct {
+		Name   string `json:"name"`
+		Secret *struct {
+			SecretName string `json:"secretName"`
+		} `json:"secret"`
+		Projected *struct {
+			Sources []struct {
+				Secret *struct {
+					Name string `json:"name"`
+				} `json:"secret"`
+			} `json:"sources"`
+		} `json:"projected"`
+	} `json:"volumes"`
+}
+
+type k8sContainer struct {
+	Name string `json:"name"`
+	Env  []struct {
+		Name      string `json:"name"`
+		ValueFrom *struct {
+			SecretKeyRef *struct {
+				Name string `json:"name"`
+				Key  string `json:"key"`
+			} `json:"secretKeyRef"`
+		} `json:"valueFrom"`
+	} `json:"env"`
+	EnvFrom []struct {
+		SecretRef *struct {
+			Name string `json:"name"`
+		} `json:"secretRef"`
+	} `json:"envFrom"`
+}
+
+// uses lists how the pod spec reads the Kubernetes secret called name
+func (p podSpec) uses(name string) []string {
+	var via []string
+	for _, c := range append(append([]k8sContainer{}, p.InitContainers...), p.Containers...) {
+		for _, e := range c.Env {
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil && e.ValueFrom.SecretKeyRef.Name == name {
+				via = append(via, fmt.Sprintf("env %s (key %s) in container %s", e.Name, e.ValueFrom.SecretKeyRef.Key, c.Name))
+			}
+		}
+		for _, e := range c.EnvFrom {
+			if e.SecretRef != nil && e.SecretRef.Name == name {
+				via = append(via, "envFrom in container "+c.Name)
+			}
+		}
+	}
+	for _, v := range p.Volumes {
+		if v.Secret != nil && v.Secret.SecretName == name {
+			via = append(via, "volume "+v.Name)
+		}
+		if v.Projected != nil {
+			for _, src := range v.Projected.Sources {
+				if src.Secret != nil && src.Secret.Name == name {
+					via = append(via, "projected volume "+v.Name)
+				}
+			}
+		}
+	}
+	for _, s := range p.ImagePullSecrets {
+		if s.Name == name {
+			via = append(via, "imagePullSecrets")
+		}
+	}
+	return via
+}
+
+// k8sReferences finds the Deployments, StatefulSets, DaemonSets and
+// CronJobs whose pods read the Kubernetes secret called name
+func (a *Agent) k8sReferences(ctx context.Context, name, namespace string) ([]Reference, error) {
+	// A namespace/name secret only matches workloads in its namespace
+	secretName := name
+	if ns, n, ok := strings.Cut(name, "/"); ok {
+		namespace, secretName = ns, n
+	}
+	args := []string{"get", "deployments,statefulsets,daemonsets,cronjobs", "-o", "json"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	} else {
+		args = append(args, "-A")
+	}
+	var resp struct {
+		Items []struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				Template struct {
+					Spec podSpec `json:"spec"`
+				} `json:"template"`
+				JobTemplate struct {
+					Spec struct {
+						Template struct {
+							Spec podSpec `json:"spec"`
+						} `json:"template"`
+					} `json:"spec"`
+				} `json:"jobTemplate"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := a.runJSON(ctx, a.runners.Kubectl, &resp, args...); err != nil {
+		return nil, err
+	}
+	var refs []Reference
+	for _, item := range resp.Items {
+		if namespace != "" && item.Metadata.Namespace != namespace {
+			continue
+		}
+		spec := item.Spec.Template.Spec
+		if item.Kind == "CronJob" {
+			spec = item.Spec.JobTemplate.Spec.Template.Spec
+		}
+		for _, via := range spec.uses(secretName) {
+			refs = append(refs, Reference{Kind: item.Kind, Name: item.Metadata.Name, Namespace: item.Metadata.Namespace, Via: via})
+		}
+	}
+	return refs, nil
+}
+
+// externalSecretReferences finds the External Secrets Operator resources
+// that sync the cloud secret called name into the cluster
+func (a *Agent) externalSecretReferences(ctx context.Context, name string) ([]Reference, error) {
+	var resp struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				Target struct {
+					Name string `json:"name"`
+				} `json:"target"`
+				Data []struct {
+					RemoteRef struct {
+						Key string `json:"key"`
+					} `json:"remoteRef"`
+				} `json:"data"`
+				DataFrom []struct {
+					Extract *struct {
+						Key string `json:"key"`
+					} `json:"extract"`
+				} `json:"dataFrom"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := a.runJSON(ctx, a.runners.Kubectl, &resp, "get", "externalsecrets.external-secrets.io", "-A", "-o", "json"); err != nil {
+		return nil, err
+	}
+	var refs []Reference
+	for _, item := range resp.Items {
+		found := false
+		for _, d := range item.Spec.Data {
+			found = found || refersTo(d.RemoteRef.Key, name)
+		}
+		for _, d := range item.Spec.DataFrom {
+			found = found || (d.Extract != nil && refersTo(d.Extract.Key, name))
+		}
+		if !found {
+			continue
+		}
+		target := item.Spec.Target.Name
+		if target == "" {
+			target = item.Metadata.Name
+		}
+		refs = append(refs, Reference{Kind: "ExternalSecret", Name: item.Metadata.Name, Namespace: item.Metadata.Namespace,
+			Via: "syncs to Secret " + target})
+	}
+	return refs, nil
+}
+
+// ecsReferences finds the latest active task definition of each family
+// whose containers take the secret called name from Secrets Manager or
+// Parameter Store
+func (a *Agent) ecsReferences(ctx context.Context, name string) ([]Reference, error) {
+	var families struct {
+		Families []string `json:"families"`
+	}
+	if err := a.runJSON(ctx, a.runners.AWS, &families, "ecs", "list-task-definition-families", "--status", "ACTIVE", "--output", "json"); err != nil {
+		return nil, err
+	}
+	var refs []Reference
+	for _, family := range families.Families {
+		var resp struct {
+			TaskDefinition struct {
+				Family               string `json:"family"`
+				Revision             int    `json:"revision"`
+				ContainerDefinitions []struct {
+					Name    string `json:"name"`
+					Secrets []struct {
+						Name      string `json:"name"`
+						ValueFrom string `json:"valueFrom"`
+					} `json:"secrets"`
+					RepositoryCredentials *struct {
+						CredentialsParameter string `json:"credentialsParameter"`
+					} `json:"repositoryCredentials"`
+				} `json:"containerDefinitions"`
+			} `json:"taskDefinition"`
+		}
+		if err := a.runJSON(ctx, a.runners.AWS, &resp, "ecs", "describe-task-definition", "--task-definition", family, "--output", "json"); err != nil {
+			return nil, err
+		}
+		td := resp.TaskDefinition
+		id := fmt.Sprintf("%s:%d", td.Family, td.Revision)
+		for _, c := range td.ContainerDefinitions {
+			for _, s := range c.Secrets {
+				if refersTo(s.ValueFrom, name) {
+					refs = append(refs, Reference{Kind: "ECS task definition", Name: id, Via: fmt.Sprintf("secret %s in container %s", s.Name, c.Name)})
+				}
+			}
+			if c.RepositoryCredentials != nil && refersTo(c.RepositoryCredentials.CredentialsParameter, name) {
+				refs = append(refs, Reference{Kind: "ECS task definition", Name: id, Via: "registry credentials of container " + c.Name})
+			}
+		}
+	}
+	return refs, nil
+}
+
+// lambdaReferences finds the Lambda functions with an environment variable
+// holding the name or ARN of the secret called name
+func (a *Agent) lambdaReferences(ctx context.Context, name string) ([]Reference, error) {
+	var resp struct {
+		Functions []struct {
+			FunctionName string
+			Environment  struct {
+				Variables map[string]string
+			}
+		}
+	}
+	if err := a.runJSON(ctx, a.runners.AWS, &resp, "lambda", "list-functions", "--output", "json"); err != nil {
+		return nil, err
+	}
+	var refs []Reference
+	for _, f := range resp.Functions {
+		var vars []string
+		for k, v := range f.Environment.Variables {
+			if refersTo(v, name) {
+				vars = append(vars, k)
+			}
+		}
+		sort.Strings(vars)
+		for _, k := range vars {
+			refs = append(refs, Reference{Kind: "Lambda function", Name: f.FunctionName, Via: "env " + k})
+		}
+	}
+	return refs, nil
+}
+
+// cloudRunReferences finds the Cloud Run services that read the Secret
+// Manager secret called name as an environment variable or a volume
+func (a *Agent) cloudRunReferences(ctx context.Context, name string) ([]Reference, error) {
+	var resp []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Template struct {
+				Spec struct {
+					Containers []struct {
+						Name string `json:"name"`
+						Env  []struct {
+							Name      string `json:"name"`
+							ValueFrom *struct {
+								SecretKeyRef *struct {
+									Name string `json:"name"`
+								} `json:"secretKeyRef"`
+							} `json:"valueFrom"`
+						} `json:"env"`
+					} `json:"containers"`
+					Volumes []struct {
+						Name   string `json:"name"`
+						Secret *struct {
+							SecretName string `json:"secretName"`
+						} `json:"secret"`
+					} `json:"volumes"`
+				} `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+	}
+	if err := a.runJSON(ctx, a.runners.GCloud, &resp, "run", "services", "list", "--format=json"); err != nil {
+		return nil, err
+	}
+	var refs []Reference
+	for _, svc := range resp {
+		spec := svc.Spec.Template.Spec
+		for _, c := range spec.Containers {
+			for _, e := range c.Env {
+				if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil && refersTo(e.ValueFrom.SecretKeyRef.Name, name) {
+					via := "env " + e.Name
+					if c.Name != "" {
+						via += " in container " + c.Name
+					}
+					refs = append(refs, Reference{Kind: "Cloud Run service", Name: svc.Metadata.Name, Via: via})
+				}
+			}
+		}
+		for _, v := range spec.Volumes {
+			if v.Secret != nil && refersTo(v.Secret.SecretName, name) {
+				refs = append(refs, Reference{Kind: "Cloud Run service", Name: svc.Metadata.Name, Via: "volume " + v.Name})
+			}
+		}
+	}
+	return refs, nil
+}
+
+// refersTo reports whether ref, a name, ARN or resource path taken from a
+// workload's configuration, points at the secret called name
+func refersTo(ref, name string) bool {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || name == "" {
+		return false
+	}
+	if ref == name {
+		return true
+	}
+	// arn:aws:secretsmanager:region:account:secret:name-AbCdEf, optionally
+	// followed by a JSON key and version stage as ECS allows
+	if _, rest, ok := strings.Cut(ref, ":secret:"); ok {
+		rest, _, _ = strings.Cut(rest, ":")
+		return rest == name || (strings.HasPrefix(rest, name+"-") && len(rest) == len(name)+7)
+	}
+	// arn:aws:ssm:region:account:parameter/name, where a path name keeps
+	// its leading slash: parameter/app/db for /app/db
+	if _, rest, ok := strings.Cut(ref, ":parameter"); ok {
+		return rest == name || rest == "/"+name
+	}
+	// projects/project/secrets/name, optionally /versions/version
+	if _, rest, ok := strings.Cut(ref, "/secrets/"); ok {
+		rest, _, _ = strings.Cut(rest, "/")
+		return rest == name
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package secrets
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/askparse"
+)
+
+// Operation is what a question asks of the secret stores
+type Operation int
+
+const (
+	// List shows secrets by their metadata
+	List Operation = iota + 1
+	// References finds the workloads that read a secret
+	References
+	// Rotate plans a new value for a secret
+	Rotate
+	// Create plans a new secret
+	Create
+)
+
+// Request is a question translated into a secrets operation
+type Request struct {
+	Op Operation
+	// Name is the secret the operation applies to
+	Name string
+	// Stores are the stores the question names; empty means every
+	// configured store
+	Stores []Store
+	// Namespace limits Kubernetes secrets to one namespace
+	Namespace string
+}
+
+var (
+	secretRe    = regexp.MustCompile(`(?i)\bsecrets?\b|\bparameter store\b|\bsecure ?strings?\b`)
+	notSecretRe = regexp.MustCompile(`(?i)\b(?:github|gitlab|actions|vercel|netlify|railway|fly\.io|flyio|key ?vault|azure|access keys?|secret access key|secret scanning|scan|leak(?:ed|s)?|exposed|hard-?coded|cost|costs|spend|bill|billing)\b`)
+	rotateRe    = regexp.MustCompile(`(?i)\brotate\b`)
+	createRe    = regexp.MustCompile(`(?i)\b(?:create|add|store|make)\s+(?:a\s+|an\s+|the\s+)?(?:new\s+)?(?:(?:aws|gcp|k8s|kubernetes|securestring|secure string|ssm)\s+)?(?:secret|parameter)\b|\bnew secret\b`)
+	refsRe      = regexp.MustCompile(`(?i)\b(?:use|uses|using|used|references?|referenced|referencing|depends? on|mounts?|mounted|consumes?|reads?)\b`)
+	listRe      = regexp.MustCompile(`(?i)\b(?:list|show|what|which|how many|any|describe|overview|inventory|all|rotation|rotated|stale|old|oldest|unused|expir\w*)\b`)
+
+	smRe         = regexp.MustCompile(`(?i)\bsecrets?[ -]?manager\b`)
+	ssmRe        = regexp.MustCompile(`(?i)\bparameter store\b|\bssm\b|\bsecure ?strings?\b|\bparameters?\b`)
+	awsRe        = regexp.MustCompile(`(?i)\baws\b`)
+	gcpRe        = regexp.MustCompile(`(?i)\b(?:gcp|google|gcloud)\b`)
+	k8sRe        = regexp.MustCompile(`(?i)\b(?:k8s|kubernetes|kubectl|namespaces?)\b`)
+	namespaceRes = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bnamespace\s+([a-z0-9][a-z0-9-]*)`),
+		regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9-]*)\s+namespace\b`),
+		regexp.MustCompile(`(?:^|\s)-n\s+([a-z0-9][a-z0-9-]*)`),
+	}
+)
+
+// notNames are words the name patterns can land on that never name a
+// secret or namespace
+var notNames = askparse.NotNames("secret", "secrets", "manager", "parameter", "parameters", "store",
+	"it", "new", "by", "named", "called", "value", "values", "are", "or",
+	"aws", "gcp", "google", "k8s", "kubernetes", "ssm", "securestring", "secure", "string", "key", "keys",
+	"namespace", "rotation", "now", "env", "environment")
+
+var secretNames = askparse.Names{
+	Patterns: []*regexp.Regexp{
+		regexp.MustCompile("[\"'`]([A-Za-z0-9/_.+=@-]+)[\"'`]"),
+		regexp.MustCompile(`(?i)\b(?:named|called)\s+([A-Za-z0-9/_.+=@-]+)`),
+		regexp.MustCompile(`(?i)\b(?:secret|parameter)\s+(?:named\s+|called\s+)?([A-Za-z0-9/_.+=@-]+)`),
+		regexp.MustCompile(`(?i)\b(?:rotate|create|add|store|uses?|using|references?|referencing|mounts?)\s+(?:the\s+|a\s+|an\s+)?([A-Za-z0-9/_.+=@-]+)`),
+	},
+	Skip: notNames,
+	Trim: ".,?!",
+}
+
+// ParseRequest reads a secrets operation from question. Questions that ask
+// for no change and name no workloads are listings.
+func ParseRequest(question string) Request {
+	req := Request{Op: List, Stores: parseStores(question)}
+	for _, re := range namespaceRes {
+		if m := re.FindStringSubmatch(question); m != nil && !notNames[strings.ToLower(m[1])] {
+			req.Namespace = strings.ToLower(m[1])
+			break
+		}
+	}
+	// The namespace would otherwise read as a secret name
+	rest := question
+	if req.Namespace != "" {
+		for _, re := range namespaceRes {
+			rest = re.ReplaceAllString(rest, " ")
+		}
+		if !containsStore(req.Stores, Kubernetes) {
+			req.Stores = append(req.Stores, Kubernetes)
+		}
+	}
+
+	switch {
+	case rotateRe.MatchString(question):
+		req.Op = Rotate
+	case createRe.MatchString(question):
+		req.Op = Create
+	case refsRe.MatchString(question):
+		req.Op = References
+	}
+	if req.Op != List {
+		req.Name = secretNames.Find(rest)
+		if req.Op == References && req.Name == "" {
+			req.Op = List
+		}
+	}
+	return req
+}
+
+// parseStores returns the stores question names
+func parseStores(question string) []Store {
+	var stores []Store
+	gcp := gcpRe.MatchString(question)
+	sm := smRe.MatchString(question) && !gcp
+	ssm := ssmRe.MatchString(question)
+	if sm || (awsRe.MatchString(question) && !ssm) {
+		stores = append(stores, SecretsManager)
+	}
+	if ssm || (awsRe.MatchString(question) && !sm) {
+		stores = append(stores, ParameterStore)
+	}
+	if gcp {
+		stores = append(stores, GCPSecretManager)
+	}
+	if k8sRe.MatchString(question) {
+		stores = append(stores, Kubernetes)
+	}
+	return stores
+}
+
+func containsStore(stores []Store, s Store) bool {
+	for _, have := range stores {
+		if have == s {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSecretsQuestion reports whether question is about the secrets in a
+// cloud secret store or a cluster, rather than CI secrets, leaked
+// credentials or IAM access keys
+func IsSecretsQuestion(question string) bool {
+	if !secretRe.MatchString(question) || notSecretRe.MatchString(question) {
+		return false
+	}
+	req := ParseRequest(question)
+	if req.Op != List {
+		return req.Name != "" || req.Op == Create
+	}
+	return listRe.MatchString(question)
+}
@@ -0,0 +1,405 @@
+// Package secrets answers questions about secrets kept in AWS Secrets
+// Manager, SSM Parameter Store, GCP Secret Manager and Kubernetes. It lists
+// secrets by their metadata, finds the workloads that read one, and turns
+// rotations and new secrets into plans whose values are supplied when the
+// plan runs. Secret values are never shown or written to a plan, and
+// everything the agent prints is redacted even when redaction is turned off.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Runner runs a CLI command and returns its output
+type Runner func(ctx context.Context, args []string) (string, error)
+
+// Runners are the CLIs the agent reaches each provider through. A nil
+// runner leaves that provider's stores out.
+type Runners struct {
+	// AWS runs aws CLI commands
+	AWS Runner
+	// GCloud runs gcloud commands in the current project
+	GCloud Runner
+	// Kubectl runs kubectl commands against the current context
+	Kubectl Runner
+}
+
+// Agent reads secret metadata and builds plans that change secrets
+type Agent struct {
+	runners Runners
+	debug   bool
+}
+
+// NewAgent creates a secrets agent that calls each provider through its
+// runner
+func NewAgent(runners Runners, debug bool) *Agent {
+	return &Agent{runners: runners, debug: debug}
+}
+
+// Store is a place secrets are kept
+type Store string
+
+const (
+	SecretsManager   Store = "secretsmanager"
+	ParameterStore   Store = "ssm"
+	GCPSecretManager Store = "gcp"
+	Kubernetes       Store = "k8s"
+)
+
+// Stores are the stores a listing covers when a question names none, in
+// the order they are shown
+var Stores = []Store{SecretsManager, ParameterStore, GCPSecretManager, Kubernetes}
+
+// Label is the store's name in listings and plan summaries
+func (s Store) Label() string {
+	switch s {
+	case SecretsManager:
+		return "AWS Secrets Manager"
+	case ParameterStore:
+		return "SSM Parameter Store"
+	case GCPSecretManager:
+		return "GCP Secret Manager"
+	case Kubernetes:
+		return "Kubernetes Secrets"
+	}
+	return string(s)
+}
+
+// runner is the runner that reaches s, or nil when its provider is not
+// configured
+func (a *Agent) runner(s Store) Runner {
+	switch s {
+	case SecretsManager, ParameterStore:
+		return a.runners.AWS
+	case GCPSecretManager:
+		return a.runners.GCloud
+	case Kubernetes:
+		return a.runners.Kubectl
+	}
+	return nil
+}
+
+// Secret is a secret's metadata; it never holds the value
+type Secret struct {
+	Store Store
+	Name  string
+	// ID is the ARN of an AWS secret or parameter, or the resource name of
+	// a GCP secret
+	ID string
+	// Namespace is the namespace of a Kubernetes secret
+	Namespace string
+	// Type is the Kubernetes secret type
+	Type string
+	// Keys are the data keys of a Kubernetes secret
+	Keys []string
+	// KMSKey is the key the secret is encrypted with, when it is not the
+	// store's default key
+	KMSKey       string
+	Created      time.Time
+	Updated      time.Time
+	LastAccessed time.Time
+	// RotationLambda is the function that rotates a Secrets Manager secret
+	RotationLambda string
+	// RotationPeriod is how often the secret is rotated, such as "30 days"
+	RotationPeriod string
+	NextRotation   time.Time
+}
+
+// Location is where the secret lives within its store: the namespace of a
+// Kubernetes secret and the name otherwise
+func (s Secret) Location() string {
+	if s.Namespace != "" {
+		return s.Namespace + "/" + s.Name
+	}
+	return s.Name
+}
+
+// Inventory is the secrets of each store a listing asked for
+type Inventory struct {
+	Stores  []Store
+	Secrets []Secret
+	// Errors holds the stores that could not be listed; the others are
+	// still shown
+	Errors map[Store]error
+}
+
+// Find returns the secrets named name, which may be namespace/name for a
+// Kubernetes secret
+func (inv *Inventory) Find(name string) []Secret {
+	var out []Secret
+	for _, s := range inv.Secrets {
+		if s.Name == name || s.Location() == name {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// timestamp decodes the ISO 8601 strings and epoch seconds the CLIs use for
+// times
+type timestamp struct {
+	time.Time
+}
+
+func (t *timestamp) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		if s == "" {
+			return nil
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return err
+		}
+		t.Time = parsed.UTC()
+		return nil
+	}
+	var secs float64
+	if err := json.Unmarshal(b, &secs); err != nil {
+		return err
+	}
+	t.Time = time.Unix(int64(secs), 0).UTC()
+	return nil
+}
+
+// runJSON runs a command that prints JSON and decodes the output into out
+func (a *Agent) runJSON(ctx context.Context, run Runner, out any, args ...string) error {
+	raw, err := run(ctx, args)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("failed to parse %s %s output: %w", args[0], args[1], err)
+	}
+	return nil
+}
+
+// List returns the secrets in stores, or in every store whose provider is
+// configured when stores is empty. Kubernetes secrets are limited to
+// namespace when it is set. A store that cannot be listed is recorded in
+// Errors rather than failing the others.
+func (a *Agent) List(ctx context.Context, stores []Store, namespace string) *Inventory {
+	if len(stores) == 0 {
+		for _, s := range Stores {
+			if a.runner(s) != nil {
+				stores = append(stores, s)
+			}
+		}
+	}
+	inv := &Inventory{Stores: stores, Errors: map[Store]error{}}
+	for _, s := range stores {
+		if a.runner(s) == nil {
+			inv.Errors[s] = fmt.Errorf("%s is not configured", s.Label())
+			continue
+		}
+		var found []Secret
+		var err error
+		switch s {
+		case SecretsManager:
+			found, err = a.awsSecrets(ctx)
+		case ParameterStore:
+			found, err = a.parameters(ctx)
+		case GCPSecretManager:
+			found, err = a.gcpSecrets(ctx)
+		case Kubernetes:
+			found, err = a.k8sSecrets(ctx, namespace)
+		}
+		if err != nil {
+			inv.Errors[s] = err
+			continue
+		}
+		inv.Secrets = append(inv.Secrets, found...)
+	}
+	if a.debug {
+		fmt.Printf("[secrets] listed %d secrets from %d stores\n", len(inv.Secrets), len(stores)-len(inv.Errors))
+	}
+	return inv
+}
+
+// awsSecrets returns every Secrets Manager secret with its rotation
+// settings
+func (a *Agent) awsSecrets(ctx context.Context) ([]Secret, error) {
+	var resp struct {
+		SecretList []struct {
+			ARN               string
+			Name              string
+			KmsKeyId          string
+			RotationLambdaARN string
+			RotationRules     struct {
+				AutomaticallyAfterDays int
+				ScheduleExpression     string
+			}
+			CreatedDate      timestamp
+			LastChangedDate  timestamp
+			LastAccessedDate timestamp
+			NextRotationDate timestamp
+		}
+	}
+	if err := a.runJSON(ctx, a.runners.AWS, &resp, "secretsmanager", "list-secrets", "--output", "json"); err != nil {
+		return nil, err
+	}
+	out := make([]Secret, 0, len(resp.SecretList))
+	for _, s := range resp.SecretList {
+		secret := Secret{
+			Store:          SecretsManager,
+			Name:           s.Name,
+			ID:             s.ARN,
+			KMSKey:         s.KmsKeyId,
+			Created:        s.CreatedDate.Time,
+			Updated:        s.LastChangedDate.Time,
+			LastAccessed:   s.LastAccessedDate.Time,
+			RotationLambda: s.RotationLambdaARN,
+			NextRotation:   s.NextRotationDate.Time,
+		}
+		switch {
+		case s.RotationRules.ScheduleExpression != "":
+			secret.RotationPeriod = s.RotationRules.ScheduleExpression
+		case s.RotationRules.AutomaticallyAfterDays > 0:
+			secret.RotationPeriod = fmt.Sprintf("%d days", s.RotationRules.AutomaticallyAfterDays)
+		}
+		out = append(out, secret)
+	}
+	sortSecrets(out)
+	return out, nil
+}
+
+// parameters returns every SecureString parameter. Plain String parameters
+// are configuration rather than secrets and are left out.
+func (a *Agent) parameters(ctx context.Context) ([]Secret, error) {
+	var resp struct {
+		Parameters []struct {
+			Name             string
+			ARN              string
+			KeyId            string
+			LastModifiedDate timestamp
+		}
+	}
+	if err := a.runJSON(ctx, a.runners.AWS, &resp, "ssm", "describe-parameters",
+		"--parameter-filters", "Key=Type,Values=SecureString", "--output", "json"); err != nil {
+		return nil, err
+	}
+	out := make([]Secret, 0, len(resp.Parameters))
+	for _, p := range resp.Parameters {
+		key := p.KeyId
+		if key == "alias/aws/ssm" {
+			key = ""
+		}
+		out = append(out, Secret{
+			Store:   ParameterStore,
+			Name:    p.Name,
+			ID:      p.ARN,
+			KMSKey:  key,
+			Updated: p.LastModifiedDate.Time,
+		})
+	}
+	sortSecrets(out)
+	return out, nil
+}
+
+// gcpSecrets returns every Secret Manager secret in the project with its
+// rotation schedule
+func (a *Agent) gcpSecrets(ctx context.Context) ([]Secret, error) {
+	var resp []struct {
+		Name       string    `json:"name"`
+		CreateTime timestamp `json:"createTime"`
+		Rotation   struct {
+			NextRotationTime timestamp `json:"nextRotationTime"`
+			RotationPeriod   string    `json:"rotationPeriod"`
+		} `json:"rotation"`
+		CustomerManagedEncryption struct {
+			KMSKeyName string `json:"kmsKeyName"`
+		} `json:"customerManagedEncryption"`
+	}
+	if err := a.runJSON(ctx, a.runners.GCloud, &resp, "secrets", "list", "--format=json"); err != nil {
+		return nil, err
+	}
+	out := make([]Secret, 0, len(resp))
+	for _, s := range resp {
+		out = append(out, Secret{
+			Store:          GCPSecretManager,
+			Name:           s.Name[strings.LastIndex(s.Name, "/")+1:],
+			ID:             s.Name,
+			KMSKey:         s.CustomerManagedEncryption.KMSKeyName,
+			Created:        s.CreateTime.Time,
+			RotationPeriod: gcpPeriod(s.Rotation.RotationPeriod),
+			NextRotation:   s.Rotation.NextRotationTime.Time,
+		})
+	}
+	sortSecrets(out)
+	return out, nil
+}
+
+// gcpPeriod turns a duration such as "2592000s" into "30 days"
+func gcpPeriod(period string) string {
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return period
+	}
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d days", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+// skippedSecretTypes are Kubernetes secret types that hold cluster
+// machinery rather than application secrets
+var skippedSecretTypes = map[string]bool{
+	"helm.sh/release.v1":                  true,
+	"kubernetes.io/service-account-token": true,
+}
+
+// k8sSecrets returns the Kubernetes secrets in namespace, or in every
+// namespace when it is empty, with the names of their keys. The values in
+// the kubectl output are dropped while decoding.
+func (a *Agent) k8sSecrets(ctx context.Context, namespace string) ([]Secret, error) {
+	args := []string{"get", "secrets", "-o", "json"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	} else {
+		args = append(args, "-A")
+	}
+	var resp struct {
+		Items []struct {
+			Metadata struct {
+				Name              string    `json:"name"`
+				Namespace         string    `json:"namespace"`
+				CreationTimestamp timestamp `json:"creationTimestamp"`
+			} `json:"metadata"`
+			Type string                     `json:"type"`
+			Data map[string]json.RawMessage `json:"data"`
+		} `json:"items"`
+	}
+	if err := a.runJSON(ctx, a.runners.Kubectl, &resp, args...); err != nil {
+		return nil, err
+	}
+	out := make([]Secret, 0, len(resp.Items))
+	for _, item := range resp.Items {
+		if skippedSecretTypes[item.Type] {
+			continue
+		}
+		keys := make([]string, 0, len(item.Data))
+		for k := range item.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out = append(out, Secret{
+			Store:     Kubernetes,
+			Name:      item.Metadata.Name,
+			Namespace: item.Metadata.Namespace,
+			Type:      item.Type,
+			Keys:      keys,
+			Created:   item.Metadata.CreationTimestamp.Time,
+		})
+	}
+	sortSecrets(out)
+	return out, nil
+}
+
+func sortSecrets(secrets []Secret) {
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Location() < secrets[j].Location() })
+}
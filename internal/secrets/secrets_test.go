@@ -0,0 +1,362 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// fakeRunner answers commands by the first two args and records every call
+func fakeRunner(calls *[][]string, outputs map[string]string) Runner {
+	return func(ctx context.Context, args []string) (string, error) {
+		*calls = append(*calls, args)
+		if out, ok := outputs[args[0]+" "+args[1]]; ok {
+			return out, nil
+		}
+		return "", errors.New("unexpected call: " + strings.Join(args, " "))
+	}
+}
+
+const (
+	listSecretsOutput = `{"SecretList": [
+		{"ARN": "arn:aws:secretsmanager:eu-west-1:111111111111:secret:prod/db-AbCdEf", "Name": "prod/db",
+		 "RotationLambdaARN": "arn:aws:lambda:eu-west-1:111111111111:function:rotate-db", "RotationRules": {"AutomaticallyAfterDays": 30},
+		 "LastChangedDate": "2026-10-01T08:00:00.123000+00:00", "NextRotationDate": "2026-10-31T00:00:00+00:00"},
+		{"ARN": "arn:aws:secretsmanager:eu-west-1:111111111111:secret:stripe-key-XyZ123", "Name": "stripe-key",
+		 "LastChangedDate": 1735689600, "LastAccessedDate": "2026-10-14T00:00:00+00:00"}
+	]}`
+	describeParametersOutput = `{"Parameters": [{"Name": "/app/token", "ARN": "arn:aws:ssm:eu-west-1:111111111111:parameter/app/token",
+		"KeyId": "alias/aws/ssm", "LastModifiedDate": "2026-09-01T00:00:00+00:00"}]}`
+	gcpSecretsOutput = `[{"name": "projects/123/secrets/api-key", "createTime": "2026-01-01T00:00:00Z",
+		"rotation": {"rotationPeriod": "2592000s", "nextRotationTime": "2026-11-01T00:00:00Z"}}]`
+	k8sSecretsOutput = `{"items": [
+		{"metadata": {"name": "db-credentials", "namespace": "shop", "creationTimestamp": "2026-02-01T00:00:00Z"},
+		 "type": "Opaque", "data": {"username": "YWRtaW4=", "password": "aHVudGVyMg=="}},
+		{"metadata": {"name": "sh.helm.release.v1.shop.v1", "namespace": "shop"}, "type": "helm.sh/release.v1", "data": {"release": "H4sI"}}
+	]}`
+)
+
+func TestList(t *testing.T) {
+	var awsCalls, gcpCalls, kubeCalls [][]string
+	agent := NewAgent(Runners{
+		AWS: fakeRunner(&awsCalls, map[string]string{
+			"secretsmanager list-secrets": listSecretsOutput,
+			"ssm describe-parameters":     describeParametersOutput,
+		}),
+		GCloud:  fakeRunner(&gcpCalls, map[string]string{"secrets list": gcpSecretsOutput}),
+		Kubectl: fakeRunner(&kubeCalls, map[string]string{"get secrets": k8sSecretsOutput}),
+	}, false)
+
+	inv := agent.List(context.Background(), nil, "")
+	if len(inv.Errors) != 0 {
+		t.Fatalf("List() errors = %v", inv.Errors)
+	}
+	if !reflect.DeepEqual(inv.Stores, Stores) {
+		t.Errorf("stores = %v, want every store", inv.Stores)
+	}
+	var names []string
+	for _, s := range inv.Secrets {
+		names = append(names, string(s.Store)+":"+s.Location())
+	}
+	want := []string{"secretsmanager:prod/db", "secretsmanager:stripe-key", "ssm:/app/token", "gcp:api-key", "k8s:shop/db-credentials"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("secrets = %v, want %v", names, want)
+	}
+
+	db := inv.Secrets[0]
+	if db.RotationPeriod != "30 days" || db.RotationLambda == "" || !db.Updated.Equal(time.Date(2026, 10, 1, 8, 0, 0, 123000000, time.UTC)) {
+		t.Errorf("prod/db = %+v", db)
+	}
+	if stripe := inv.Secrets[1]; !stripe.Updated.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("epoch LastChangedDate = %v", stripe.Updated)
+	}
+	if param := inv.Secrets[2]; param.KMSKey != "" || param.ID != "arn:aws:ssm:eu-west-1:111111111111:parameter/app/token" {
+		t.Errorf("/app/token = %+v", param)
+	}
+	if gcp := inv.Secrets[3]; gcp.RotationPeriod != "30 days" || gcp.ID != "projects/123/secrets/api-key" {
+		t.Errorf("api-key = %+v", gcp)
+	}
+	if k := inv.Secrets[4]; !reflect.DeepEqual(k.Keys, []string{"password", "username"}) {
+		t.Errorf("db-credentials keys = %v", k.Keys)
+	}
+	if !reflect.DeepEqual(kubeCalls[0], []string{"get", "secrets", "-o", "json", "-A"}) {
+		t.Errorf("kubectl args = %v", kubeCalls[0])
+	}
+
+	out := inv.Format(testNow)
+	for _, want := range []string{
+		"AWS Secrets Manager (2):",
+		"every 30 days, next 2026-10-31",
+		"keys: password,username",
+		"Not changed in 90 days and not rotated automatically:\n  AWS Secrets Manager stripe-key (652 days)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Format() missing %q:\n%s", want, out)
+		}
+	}
+	for _, value := range []string{"YWRtaW4=", "aHVudGVyMg==", "hunter2"} {
+		if strings.Contains(out, value) {
+			t.Errorf("Format() leaked a secret value %q:\n%s", value, out)
+		}
+	}
+}
+
+func TestListRecordsStoreErrors(t *testing.T) {
+	var calls [][]string
+	agent := NewAgent(Runners{Kubectl: fakeRunner(&calls, nil)}, false)
+	inv := agent.List(context.Background(), []Store{Kubernetes, GCPSecretManager}, "shop")
+	if inv.Errors[Kubernetes] == nil || inv.Errors[GCPSecretManager] == nil {
+		t.Fatalf("errors = %v, want both stores", inv.Errors)
+	}
+	if !reflect.DeepEqual(calls[0], []string{"get", "secrets", "-o", "json", "-n", "shop"}) {
+		t.Errorf("kubectl args = %v", calls[0])
+	}
+	if out := inv.Format(testNow); !strings.Contains(out, "GCP Secret Manager: not listed (GCP Secret Manager is not configured)") {
+		t.Errorf("Format() = %s", out)
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     Request
+	}{
+		{"list all secrets", Request{Op: List}},
+		{"list secrets in secrets manager", Request{Op: List, Stores: []Store{SecretsManager}}},
+		{"show kubernetes secrets in namespace shop", Request{Op: List, Stores: []Store{Kubernetes}, Namespace: "shop"}},
+		{"which secrets in the shop namespace are old", Request{Op: List, Stores: []Store{Kubernetes}, Namespace: "shop"}},
+		{"which deployments use secret db-credentials", Request{Op: References, Name: "db-credentials"}},
+		{"what uses the secret prod/db", Request{Op: References, Name: "prod/db"}},
+		{"rotate secret prod/db in secrets manager", Request{Op: Rotate, Name: "prod/db", Stores: []Store{SecretsManager}}},
+		{"rotate the /app/token parameter in parameter store", Request{Op: Rotate, Name: "/app/token", Stores: []Store{ParameterStore}}},
+		{"rotate gcp secret api-key", Request{Op: Rotate, Name: "api-key", Stores: []Store{GCPSecretManager}}},
+		{"create a new secret stripe-webhook in aws", Request{Op: Create, Name: "stripe-webhook", Stores: []Store{SecretsManager, ParameterStore}}},
+		{"create a secret in gcp named api-key", Request{Op: Create, Name: "api-key", Stores: []Store{GCPSecretManager}}},
+		{"create a k8s secret named api-token in namespace shop", Request{Op: Create, Name: "api-token", Stores: []Store{Kubernetes}, Namespace: "shop"}},
+	}
+	for _, tt := range tests {
+		if got := ParseRequest(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestIsSecretsQuestion(t *testing.T) {
+	for _, q := range []string{
+		"list all secrets",
+		"which deployments use secret db-credentials",
+		"rotate secret prod/db",
+		"create a secret in gcp named api-key",
+		"show SecureString parameters in parameter store",
+	} {
+		if !IsSecretsQuestion(q) {
+			t.Errorf("IsSecretsQuestion(%q) = false, want true", q)
+		}
+	}
+	for _, q := range []string{
+		"list github actions secrets",
+		"rotate access keys for user alice",
+		"scan the repo for leaked secrets",
+		"list secrets in azure key vault",
+		"rotate the secret",
+		"why can't my pod mount the secret",
+	} {
+		if IsSecretsQuestion(q) {
+			t.Errorf("IsSecretsQuestion(%q) = true, want false", q)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	var kubeCalls, awsCalls, gcpCalls [][]string
+	agent := NewAgent(Runners{
+		Kubectl: func(ctx context.Context, args []string) (string, error) {
+			kubeCalls = append(kubeCalls, args)
+			if args[1] == "externalsecrets.external-secrets.io" {
+				return `{"items": [{"metadata": {"name": "db", "namespace": "shop"}, "spec": {"target": {"name": "db-credentials"},
+					"data": [{"secretKey": "password", "remoteRef": {"key": "prod/db"}}]}}]}`, nil
+			}
+			return `{"items": [
+				{"kind": "Deployment", "metadata": {"name": "api", "namespace": "shop"}, "spec": {"template": {"spec": {
+					"containers": [{"name": "api", "env": [{"name": "DB_PASSWORD", "valueFrom": {"secretKeyRef": {"name": "prod/db", "key": "password"}}}]}]}}}},
+				{"kind": "CronJob", "metadata": {"name": "backup", "namespace": "shop"}, "spec": {"jobTemplate": {"spec": {"template": {"spec": {
+					"containers": [{"name": "backup"}], "volumes": [{"name": "creds", "secret": {"secretName": "prod/db"}}]}}}}}}
+			]}`, nil
+		},
+		AWS: fakeRunner(&awsCalls, map[string]string{
+			"ecs list-task-definition-families": `{"families": ["web"]}`,
+			"ecs describe-task-definition": `{"taskDefinition": {"family": "web", "revision": 7, "containerDefinitions": [{"name": "app",
+				"secrets": [{"name": "DB_PASSWORD", "valueFrom": "arn:aws:secretsmanager:eu-west-1:111111111111:secret:prod/db-AbCdEf:password::"},
+				            {"name": "OTHER", "valueFrom": "arn:aws:secretsmanager:eu-west-1:111111111111:secret:prod/db-replica-AbCdEf"}]}]}}`,
+			"lambda list-functions": `{"Functions": [{"FunctionName": "worker", "Environment": {"Variables": {"DB_SECRET_ID": "prod/db", "DB_PASSWORD": "hunter2"}}}]}`,
+		}),
+		GCloud: fakeRunner(&gcpCalls, map[string]string{"run services": `[]`}),
+	}, false)
+
+	report := agent.References(context.Background(), "prod/db", "")
+	if len(report.Errors) != 0 {
+		t.Fatalf("References() errors = %v", report.Errors)
+	}
+	var got []string
+	for _, r := range report.References {
+		got = append(got, r.Kind+" "+r.Name+": "+r.Via)
+	}
+	want := []string{
+		"ECS task definition web:7: secret DB_PASSWORD in container app",
+		"ExternalSecret db: syncs to Secret db-credentials",
+		"Lambda function worker: env DB_SECRET_ID",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("references = %v, want %v", got, want)
+	}
+	// prod/db reads as namespace prod, secret db, which no workload uses
+	if !reflect.DeepEqual(kubeCalls[0], []string{"get", "deployments,statefulsets,daemonsets,cronjobs", "-o", "json", "-n", "prod"}) {
+		t.Errorf("kubectl args = %v", kubeCalls[0])
+	}
+	if out := report.Format(); strings.Contains(out, "hunter2") {
+		t.Errorf("Format() leaked an environment value:\n%s", out)
+	}
+
+	k8sReport := agent.References(context.Background(), "shop/prod-db", "")
+	if len(k8sReport.References) != 0 {
+		t.Errorf("shop/prod-db references = %+v", k8sReport.References)
+	}
+}
+
+func TestK8sReferences(t *testing.T) {
+	agent := NewAgent(Runners{Kubectl: func(ctx context.Context, args []string) (string, error) {
+		return `{"items": [
+			{"kind": "Deployment", "metadata": {"name": "api", "namespace": "shop"}, "spec": {"template": {"spec": {
+				"imagePullSecrets": [{"name": "db-credentials"}],
+				"initContainers": [{"name": "migrate", "envFrom": [{"secretRef": {"name": "db-credentials"}}]}],
+				"containers": [{"name": "api", "env": [{"name": "DB_PASSWORD", "valueFrom": {"secretKeyRef": {"name": "db-credentials", "key": "password"}}}]}]}}}},
+			{"kind": "CronJob", "metadata": {"name": "backup", "namespace": "shop"}, "spec": {"jobTemplate": {"spec": {"template": {"spec": {
+				"containers": [{"name": "backup"}], "volumes": [{"name": "creds", "secret": {"secretName": "db-credentials"}}]}}}}}},
+			{"kind": "Deployment", "metadata": {"name": "api", "namespace": "staging"}, "spec": {"template": {"spec": {
+				"containers": [{"name": "api", "envFrom": [{"secretRef": {"name": "db-credentials"}}]}]}}}}
+		]}`, nil
+	}}, false)
+
+	refs, err := agent.k8sReferences(context.Background(), "shop/db-credentials", "")
+	if err != nil {
+		t.Fatalf("k8sReferences() error = %v", err)
+	}
+	want := []Reference{
+		{Kind: "Deployment", Name: "api", Namespace: "shop", Via: "envFrom in container migrate"},
+		{Kind: "Deployment", Name: "api", Namespace: "shop", Via: "env DB_PASSWORD (key password) in container api"},
+		{Kind: "Deployment", Name: "api", Namespace: "shop", Via: "imagePullSecrets"},
+		{Kind: "CronJob", Name: "backup", Namespace: "shop", Via: "volume creds"},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("k8sReferences() = %+v, want %+v", refs, want)
+	}
+}
+
+func TestRefersTo(t *testing.T) {
+	tests := []struct {
+		ref, name string
+		want      bool
+	}{
+		{"prod/db", "prod/db", true},
+		{"arn:aws:secretsmanager:eu-west-1:111111111111:secret:prod/db-AbCdEf", "prod/db", true},
+		{"arn:aws:secretsmanager:eu-west-1:111111111111:secret:prod/db-AbCdEf:password:AWSCURRENT:", "prod/db", true},
+		{"arn:aws:secretsmanager:eu-west-1:111111111111:secret:prod/db-replica-AbCdEf", "prod/db", false},
+		{"arn:aws:ssm:eu-west-1:111111111111:parameter/app/token", "/app/token", true},
+		{"arn:aws:ssm:eu-west-1:111111111111:parameter/token", "token", true},
+		{"projects/123/secrets/api-key/versions/latest", "api-key", true},
+		{"projects/123/secrets/api-key-old", "api-key", false},
+		{"", "api-key", false},
+	}
+	for _, tt := range tests {
+		if got := refersTo(tt.ref, tt.name); got != tt.want {
+			t.Errorf("refersTo(%q, %q) = %v, want %v", tt.ref, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildRotation(t *testing.T) {
+	refs := []Reference{
+		{Kind: "Deployment", Name: "api", Namespace: "shop", Via: "envFrom in container api"},
+		{Kind: "Deployment", Name: "api", Namespace: "shop", Via: "volume creds"},
+		{Kind: "CronJob", Name: "backup", Namespace: "shop", Via: "volume creds"},
+	}
+
+	withLambda := BuildRotation(Secret{Store: SecretsManager, Name: "prod/db", ID: "arn:db", RotationLambda: "arn:aws:lambda:fn"}, nil, "rotate prod/db", testNow)
+	if got := withLambda.Plan.Commands[0].Args; !reflect.DeepEqual(got, []string{"secretsmanager", "rotate-secret", "--secret-id", "arn:db"}) {
+		t.Errorf("rotation function args = %v", got)
+	}
+	if withLambda.Plan.Summary != "Rotate Secrets Manager secret prod/db with its rotation function" {
+		t.Errorf("summary = %q", withLambda.Plan.Summary)
+	}
+
+	manual := BuildRotation(Secret{Store: SecretsManager, Name: "stripe-key", ID: "arn:stripe"}, nil, "rotate stripe-key", testNow)
+	if got := manual.Plan.Commands[0].Args; !reflect.DeepEqual(got, []string{"secretsmanager", "put-secret-value", "--secret-id", "arn:stripe", "--secret-string", "<NEW_SECRET_VALUE>"}) {
+		t.Errorf("manual rotation args = %v", got)
+	}
+	if !strings.Contains(strings.Join(manual.Plan.Notes, "\n"), "Export NEW_SECRET_VALUE") {
+		t.Errorf("notes = %v", manual.Plan.Notes)
+	}
+
+	param := BuildRotation(Secret{Store: ParameterStore, Name: "/app/token", KMSKey: "alias/app"}, nil, "q", testNow)
+	if got := param.Plan.Commands[0].Args; !reflect.DeepEqual(got, []string{"ssm", "put-parameter", "--name", "/app/token", "--type", "SecureString", "--overwrite", "--value", "<NEW_SECRET_VALUE>", "--key-id", "alias/app"}) {
+		t.Errorf("parameter args = %v", got)
+	}
+
+	gcp := BuildRotation(Secret{Store: GCPSecretManager, Name: "api-key"}, nil, "q", testNow)
+	if gcp.Plan.Provider != "gcp" || !reflect.DeepEqual(gcp.Plan.Commands[0].Args, []string{"secrets", "versions", "add", "api-key", "--data-file=<NEW_SECRET_FILE>"}) {
+		t.Errorf("gcp plan = %+v", gcp.Plan)
+	}
+
+	k8s := BuildRotation(Secret{Store: Kubernetes, Name: "db-credentials", Namespace: "shop", Type: "Opaque", Keys: []string{"password", "username"}}, refs, "q", testNow)
+	if k8s.Plan != nil {
+		t.Fatalf("kubernetes rotation should not be a maker plan")
+	}
+	wantSteps := []string{
+		"kubectl create secret generic db-credentials -n shop --from-file=password=./password --from-file=username=./username --dry-run=client -o yaml | kubectl apply -f -",
+		"kubectl rollout restart deployment/api -n shop",
+	}
+	if !reflect.DeepEqual(k8s.Steps, wantSteps) {
+		t.Errorf("steps = %v, want %v", k8s.Steps, wantSteps)
+	}
+	out := k8s.Format()
+	if !strings.Contains(out, "may need a restart or redeploy to pick up the new value: Deployment shop/api, CronJob shop/backup") {
+		t.Errorf("Format() = %s", out)
+	}
+}
+
+func TestBuildCreation(t *testing.T) {
+	sm := BuildCreation(SecretsManager, "stripe-webhook", "", "q", testNow)
+	if got := sm.Plan.Commands[0].Args; !reflect.DeepEqual(got, []string{"secretsmanager", "create-secret", "--name", "stripe-webhook", "--secret-string", "<NEW_SECRET_VALUE>"}) {
+		t.Errorf("secrets manager args = %v", got)
+	}
+	gcp := BuildCreation(GCPSecretManager, "api-key", "", "q", testNow)
+	if got := gcp.Plan.Commands[0].Args; !reflect.DeepEqual(got, []string{"secrets", "create", "api-key", "--replication-policy=automatic", "--data-file=<NEW_SECRET_FILE>"}) {
+		t.Errorf("gcp args = %v", got)
+	}
+	k8s := BuildCreation(Kubernetes, "api-token", "", "q", testNow)
+	if k8s.Summary != "Create Kubernetes secret default/api-token" || k8s.Steps[0] != "kubectl create secret generic api-token -n default --from-file=<key>=./<key>" {
+		t.Errorf("kubernetes change = %+v", k8s)
+	}
+}
+
+func TestPlanRefusesExistingSecret(t *testing.T) {
+	var calls [][]string
+	agent := NewAgent(Runners{AWS: fakeRunner(&calls, map[string]string{"secretsmanager list-secrets": listSecretsOutput})}, false)
+	_, err := agent.Plan(context.Background(), Request{Op: Create, Name: "stripe-key", Stores: []Store{SecretsManager}}, "q", testNow)
+	if err == nil || !strings.Contains(err.Error(), "already has a secret named stripe-key") {
+		t.Errorf("Plan() error = %v", err)
+	}
+	_, err = agent.Plan(context.Background(), Request{Op: Create, Name: "x"}, "q", testNow)
+	if err == nil || !strings.Contains(err.Error(), "say where to create the secret") {
+		t.Errorf("Plan() without a store error = %v", err)
+	}
+	_, err = agent.Plan(context.Background(), Request{Op: Rotate, Name: "missing", Stores: []Store{SecretsManager}}, "q", testNow)
+	if err == nil || !strings.Contains(err.Error(), "no secret named missing found") {
+		t.Errorf("Plan() for a missing secret error = %v", err)
+	}
+}
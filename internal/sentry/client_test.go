@@ -256,6 +256,25 @@ func TestUpdateIssues_EscapesMaliciousID(t *testing.T) {
 	}
 }
 
+func TestResolveShortID(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/0/organizations/test-org/shortids/BACKEND-42/" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"organizationSlug":"test-org","projectSlug":"backend","groupId":"4012345","group":{"id":"4012345","shortId":"BACKEND-42","title":"TypeError"}}`)
+	}))
+	defer ts.Close()
+
+	c := newTestClient(t, ts)
+	issue, err := c.ResolveShortID(context.Background(), "", "BACKEND-42")
+	if err != nil {
+		t.Fatalf("ResolveShortID: %v", err)
+	}
+	if issue.ID != "4012345" || issue.ShortID != "BACKEND-42" {
+		t.Errorf("issue = %+v, want id 4012345", issue)
+	}
+}
+
 func TestProjectStatsPoint_UnmarshalJSON(t *testing.T) {
 	var pts []ProjectStatsPoint
 	if err := json.Unmarshal([]byte(`[[1700000000, 42], [1700003600, 17]]`), &pts); err != nil {
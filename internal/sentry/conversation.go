@@ -1,15 +1,12 @@
 package sentry
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/bgdnvk/clanker/internal/convhistory"
 )
 
 // ConversationEntry is a single Q&A turn against the Sentry ask agent.
@@ -44,15 +41,12 @@ func NewConversationHistory(orgSlug string) *ConversationHistory {
 func (h *ConversationHistory) AddEntry(question, answer, orgSlug string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.Entries = append(h.Entries, ConversationEntry{
+	h.Entries = convhistory.Append(h.Entries, ConversationEntry{
 		Timestamp: time.Now(),
 		Question:  question,
 		Answer:    answer,
 		OrgSlug:   orgSlug,
-	})
-	if len(h.Entries) > MaxHistoryEntries {
-		h.Entries = h.Entries[len(h.Entries)-MaxHistoryEntries:]
-	}
+	}, MaxHistoryEntries)
 }
 
 // UpdateAccountStatus stashes the latest snapshot so follow-up questions can
@@ -108,45 +102,18 @@ func (h *ConversationHistory) GetAccountStatusContext() string {
 	)
 }
 
-func historyPath(orgSlug string) (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-	dir := filepath.Join(home, ".clanker")
-	if err := secfile.EnsurePrivateDir(dir); err != nil {
-		return "", err
-	}
-	return filepath.Join(dir, fmt.Sprintf("sentry-%s.json", secfile.SafeSlug(orgSlug))), nil
+// Save persists the conversation history to ~/.clanker/conversations/sentry_<orgslug>.json
+func (h *ConversationHistory) Save() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Entries = convhistory.FitSize(h.Entries, convhistory.MaxBytes())
+	return convhistory.Save("sentry", h.OrgSlug, h)
 }
 
+// Load loads conversation history from disk
 func (h *ConversationHistory) Load() error {
-	path, err := historyPath(h.OrgSlug)
-	if err != nil {
-		return err
-	}
-	data, err := secfile.ReadPrivate(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
-	}
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	return json.Unmarshal(data, h)
-}
-
-func (h *ConversationHistory) Save() error {
-	path, err := historyPath(h.OrgSlug)
-	if err != nil {
-		return err
-	}
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	data, err := json.MarshalIndent(h, "", "  ")
-	if err != nil {
-		return err
-	}
-	return secfile.WritePrivate(path, data)
+	_, err := convhistory.Load("sentry", h.OrgSlug, h)
+	return err
 }
@@ -26,8 +26,8 @@ func TestConversationHistory_RoundTrip(t *testing.T) {
 		t.Fatalf("Save: %v", err)
 	}
 
-	// File must land in ~/.clanker/sentry-acme.json.
-	path := filepath.Join(tmpHome, ".clanker", "sentry-acme.json")
+	// File must land in ~/.clanker/conversations/sentry_acme.json.
+	path := filepath.Join(tmpHome, ".clanker", "conversations", "sentry_acme.json")
 	if _, err := os.Stat(path); err != nil {
 		t.Fatalf("expected history file at %s: %v", path, err)
 	}
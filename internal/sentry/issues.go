@@ -64,6 +64,26 @@ func (c *Client) GetIssue(ctx context.Context, issueID string) (*Issue, error) {
 	return &issue, nil
 }
 
+// ResolveShortID looks up an issue by the short ID Sentry shows in its UI
+// (e.g. BACKEND-42), which the issue endpoints do not accept.
+func (c *Client) ResolveShortID(ctx context.Context, orgSlug, shortID string) (*Issue, error) {
+	org := c.resolveOrg(orgSlug)
+	if org == "" || shortID == "" {
+		return nil, fmt.Errorf("org slug and short ID are required")
+	}
+	_, body, err := c.Do(ctx, "GET", fmt.Sprintf("/organizations/%s/shortids/%s/", org, url.PathEscape(shortID)), nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Group Issue `json:"group"`
+	}
+	if err := DecodeJSON(body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Group, nil
+}
+
 // GetIssueEvents returns events for a given issue, newest first. Limit caps
 // the number returned (Sentry's per-page max is 100).
 func (c *Client) GetIssueEvents(ctx context.Context, issueID string, limit int) ([]Event, error) {
@@ -0,0 +1,83 @@
+package triage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+	"github.com/bgdnvk/clanker/internal/sentry"
+)
+
+// OpPlan is a change planned from an OpRequest, with the notes the caller
+// prints next to it.
+type OpPlan struct {
+	Plan  *maker.Plan
+	Notes []string
+}
+
+// BuildOpPlan plans resolving, ignoring or assigning issues in org as
+// sentry-api commands, one PUT per issue so each can be read on its own in
+// the plan.
+func BuildOpPlan(req OpRequest, org string, issues []sentry.Issue, question string, now time.Time) (*OpPlan, error) {
+	if len(issues) == 0 {
+		return nil, fmt.Errorf("name the issues to change by short ID, e.g. BACKEND-42")
+	}
+	out := &OpPlan{}
+	var update sentry.IssueUpdate
+	var verb string
+	switch req.Op {
+	case OpResolve:
+		update, verb = sentry.IssueUpdate{Status: sentry.IssueStatusResolved}, "Resolve"
+		out.Notes = append(out.Notes, "Sentry reopens a resolved issue as a regression if it happens again.")
+	case OpIgnore:
+		update, verb = sentry.IssueUpdate{Status: sentry.IssueStatusIgnored}, "Ignore"
+		out.Notes = append(out.Notes, "Ignored issues are hidden from the default issue search and stop alerting until they are unignored.")
+	case OpAssign:
+		if req.Assignee == "" {
+			return nil, fmt.Errorf("name who to assign the issues to, e.g. \"assign BACKEND-42 to jane@example.com\"")
+		}
+		update, verb = sentry.IssueUpdate{AssignedTo: req.Assignee}, "Assign"
+		out.Notes = append(out.Notes, fmt.Sprintf("%s must be a member of %s with access to the project; Sentry rejects anyone else.", req.Assignee, org))
+	default:
+		return nil, fmt.Errorf("%s does not change anything", req.Op)
+	}
+	body, err := json.Marshal(update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode issue update: %w", err)
+	}
+
+	names := make([]string, 0, len(issues))
+	cmds := make([]maker.Command, 0, len(issues))
+	for _, issue := range issues {
+		name := issue.ShortID
+		if name == "" {
+			name = issue.ID
+		}
+		names = append(names, name)
+		reason := fmt.Sprintf("%s %s: %s", verb, name, truncate(issue.Title, titleWidth))
+		if req.Op == OpAssign {
+			reason = fmt.Sprintf("Assign %s to %s: %s", name, req.Assignee, truncate(issue.Title, titleWidth))
+		}
+		cmds = append(cmds, maker.Command{
+			Args:   []string{"sentry-api", "PUT", fmt.Sprintf("/organizations/%s/issues/?id=%s", org, url.QueryEscape(issue.ID)), string(body)},
+			Reason: reason,
+		})
+	}
+
+	summary := fmt.Sprintf("%s %s in %s", verb, strings.Join(names, ", "), org)
+	if req.Op == OpAssign {
+		summary = fmt.Sprintf("Assign %s to %s in %s", strings.Join(names, ", "), req.Assignee, org)
+	}
+	out.Plan = &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "sentry",
+		Question:  question,
+		Summary:   summary,
+		Commands:  cmds,
+	}
+	return out, nil
+}
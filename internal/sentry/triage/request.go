@@ -0,0 +1,173 @@
+package triage
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/askparse"
+)
+
+// Op is a Sentry triage operation an ask question can be answered with
+// directly, without going through the LLM context agent.
+type Op string
+
+const (
+	OpTopIssues     Op = "top-issues"
+	OpSearchIssues  Op = "search-issues"
+	OpReleaseHealth Op = "release-health"
+	OpResolve       Op = "resolve"
+	OpIgnore        Op = "ignore"
+	OpAssign        Op = "assign"
+)
+
+// Mutates reports whether the op changes an issue, in which case it is
+// planned rather than run.
+func (o Op) Mutates() bool {
+	switch o {
+	case OpResolve, OpIgnore, OpAssign:
+		return true
+	}
+	return false
+}
+
+// OpRequest is a question translated into one Sentry operation
+type OpRequest struct {
+	Op          Op
+	Project     string
+	Release     string
+	Environment string
+	// Period is a Sentry statsPeriod such as 24h or 7d. Empty means
+	// Sentry's default of 14d.
+	Period string
+	// Crashes limits the issues to unhandled errors
+	Crashes bool
+	// Issues are the short IDs (BACKEND-42) or numeric IDs to change
+	Issues []string
+	// Assignee is the username or email an assign gives the issues to
+	Assignee string
+}
+
+var (
+	// notNames are words the name patterns pick up in ordinary phrasing
+	notNames = askparse.NotNames("each", "every", "sentry", "latest", "last", "health",
+		"issues", "issue", "errors", "crashes", "since", "adoption", "new", "current")
+
+	opProjectNames = askparse.Names{
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?:^|\s)--project[\s=]+([A-Za-z0-9][A-Za-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\bproject\s+(?:named\s+|called\s+)?["'` + "`" + `]?([a-z0-9][a-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9._-]*)\s+(?:sentry\s+)?project\b`),
+		},
+		Skip: notNames,
+	}
+	opReleaseNames = askparse.Names{
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?:^|\s)--release[\s=]+(\S+)`),
+			regexp.MustCompile(`(?i)\b(?:release|version)\s+["'` + "`" + `]?([a-z0-9][\w.+@-]*)`),
+		},
+		Skip: notNames,
+	}
+	opEnvironmentNames = askparse.Names{
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?:^|\s)--environment[\s=]+([A-Za-z0-9][A-Za-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\b(?:environment|env)\s+(?:named\s+|called\s+)?["'` + "`" + `]?([a-z0-9][a-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\b(production|prod|staging|development)\b`),
+		},
+		Skip: notNames,
+	}
+
+	// opIssueRefRe matches short IDs such as BACKEND-42 or WEB-APP-1K, and
+	// numeric issue IDs written as issue 4012345 or #4012345
+	opIssueRefRe  = regexp.MustCompile(`\b([A-Z][A-Z0-9]*(?:-[A-Z0-9]+)*-[0-9][0-9A-Z]*)\b|(?i:\bissues?\s+)#?(\d{4,})\b|(?:^|\s)#(\d{4,})\b`)
+	opAssigneeRe  = regexp.MustCompile(`(?i)\bassign\b.*?\bto\s+@?([\w.+-]+(?:@[\w.-]+)?)`)
+	opResolveRe   = regexp.MustCompile(`(?i)\bresolve\b|\bmark\b.*\b(?:resolved|fixed)\b|\bclose\b`)
+	opIgnoreRe    = regexp.MustCompile(`(?i)\b(?:ignore|mute|archive)\b`)
+	opAssignRe    = regexp.MustCompile(`(?i)\bassign\b`)
+	opHealthRe    = regexp.MustCompile(`(?i)\brelease\s+health\b|\bcrash[- ]free\b|\bsession\s+health\b|\bhow\s+(?:healthy|stable)\s+is\b.*\brelease\b`)
+	opIssuesRe    = regexp.MustCompile(`(?i)\bissues?\b|\berrors\b|\bexceptions\b|\bcrash(?:es|ers)\b`)
+	opTopRe       = regexp.MustCompile(`(?i)\btop\b|\bmost\s+(?:frequent|common)\b|\bnoisiest\b|\bworst\b|\bbiggest\b`)
+	opReadRe      = regexp.MustCompile(`(?i)\b(?:list|show|get|find|search|what|which|any|new|recent|unresolved)\b`)
+	opCrashRe     = regexp.MustCompile(`(?i)\bcrash(?:es|ers|ing|ed)?\b|\bunhandled\b`)
+	opSentryRe    = regexp.MustCompile(`(?i)\bsentry\b`)
+	opTriageRe    = regexp.MustCompile(`(?i)\bcrash(?:es|ers)\b|\brelease\s+health\b|\bcrash[- ]free\b`)
+	opReleaseWord = regexp.MustCompile(`(?i)\brelease\b|--release\b`)
+	// opCanRe asks whether a change can be made, which Sentry questions
+	// such as "can I ignore these crashes" mean as a how-to
+	opCanRe = regexp.MustCompile(`(?i)^\s*can\b`)
+
+	opPeriods = []struct {
+		re     *regexp.Regexp
+		period string
+	}{
+		{regexp.MustCompile(`(?i)\b(?:today|24\s*h(?:ours?)?|last\s+day|past\s+day)\b`), "24h"},
+		{regexp.MustCompile(`(?i)\b(?:two|2)\s+weeks?\b|\b14\s*d(?:ays)?\b|\bfortnight\b`), "14d"},
+		{regexp.MustCompile(`(?i)\bweek\b|\b7\s*d(?:ays)?\b`), "7d"},
+		{regexp.MustCompile(`(?i)\bmonth\b|\b30\s*d(?:ays)?\b`), "30d"},
+	}
+)
+
+// ParseOpRequest reads the Sentry operation question asks for. The zero
+// OpRequest means the question is better answered by the context agent.
+func ParseOpRequest(question string) OpRequest {
+	req := OpRequest{
+		Project:     opProjectNames.Find(question),
+		Environment: opEnvironmentNames.Find(question),
+		Period:      opPeriod(question),
+		Crashes:     opCrashRe.MatchString(question),
+		Issues:      issueRefs(question),
+	}
+	if opReleaseWord.MatchString(question) {
+		req.Release = strings.TrimRight(opReleaseNames.Find(question), ".,;:!?")
+	}
+
+	mentionsIssues := len(req.Issues) > 0 || opIssuesRe.MatchString(question)
+	switch {
+	case opAssignRe.MatchString(question) && mentionsIssues:
+		req.Op = OpAssign
+		if m := opAssigneeRe.FindStringSubmatch(question); m != nil {
+			req.Assignee = strings.TrimRight(m[1], ".,;:!?")
+		}
+	case opIgnoreRe.MatchString(question) && mentionsIssues:
+		req.Op = OpIgnore
+	case opResolveRe.MatchString(question) && mentionsIssues:
+		req.Op = OpResolve
+	case opHealthRe.MatchString(question):
+		req.Op = OpReleaseHealth
+	case opIssuesRe.MatchString(question) && opTopRe.MatchString(question):
+		req.Op = OpTopIssues
+	case opIssuesRe.MatchString(question) && (opReadRe.MatchString(question) || req.Release != "" || req.Environment != ""):
+		req.Op = OpSearchIssues
+	}
+	if req.Op == "" || (req.Op.Mutates() && (askparse.AsksHowTo(question) || opCanRe.MatchString(question))) {
+		return OpRequest{}
+	}
+	return req
+}
+
+// IsTriageQuestion reports whether question is about Sentry without
+// naming it: crashers, crash-free rates or release health.
+func IsTriageQuestion(question string) bool {
+	return opSentryRe.MatchString(question) || opTriageRe.MatchString(question)
+}
+
+func opPeriod(question string) string {
+	for _, p := range opPeriods {
+		if p.re.MatchString(question) {
+			return p.period
+		}
+	}
+	return ""
+}
+
+func issueRefs(question string) []string {
+	var refs []string
+	seen := map[string]bool{}
+	for _, m := range opIssueRefRe.FindAllStringSubmatch(question, -1) {
+		ref := m[1] + m[2] + m[3]
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,281 @@
+// Package triage answers Sentry issue and release-health questions from
+// ask directly, and plans resolve, ignore and assign changes for
+// `ask --apply`.
+package triage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/sentry"
+)
+
+// API is the part of sentry.Client the triage agent calls, so tests can
+// stand in for Sentry.
+type API interface {
+	ListIssues(ctx context.Context, orgSlug string, opts sentry.IssueListOptions) ([]sentry.Issue, string, error)
+	GetIssue(ctx context.Context, issueID string) (*sentry.Issue, error)
+	ResolveShortID(ctx context.Context, orgSlug, shortID string) (*sentry.Issue, error)
+	ListReleases(ctx context.Context, orgSlug, projectSlug string) ([]sentry.Release, error)
+	GetReleaseHealth(ctx context.Context, orgSlug, projectSlug, version string) (*sentry.SessionsResponse, error)
+}
+
+const (
+	// topIssuesLimit and searchIssuesLimit cap the issue listings
+	topIssuesLimit    = 10
+	searchIssuesLimit = 25
+	// titleWidth truncates issue titles so tables stay readable
+	titleWidth = 80
+)
+
+var numericIDRe = regexp.MustCompile(`^\d+$`)
+
+// Ops answers OpRequests for one organization. Reads run straight against
+// the Sentry API; changes are built into plans by BuildOpPlan and only run
+// through `ask --apply`.
+type Ops struct {
+	api API
+	org string
+	now time.Time
+}
+
+// NewOps returns a triage agent over api for org. now dates issues in
+// listings.
+func NewOps(api API, org string, now time.Time) *Ops {
+	return &Ops{api: api, org: org, now: now}
+}
+
+// Read searches the org's issues or reports a release's health, rendered
+// as text.
+func (o *Ops) Read(ctx context.Context, req OpRequest) (string, error) {
+	switch req.Op {
+	case OpTopIssues, OpSearchIssues:
+		opts := issueOptions(req)
+		issues, _, err := o.api.ListIssues(ctx, o.org, opts)
+		if err != nil {
+			return "", err
+		}
+		return o.formatIssues(describeSearch(req, opts), issues), nil
+	case OpReleaseHealth:
+		return o.releaseHealth(ctx, req)
+	}
+	return "", fmt.Errorf("%s is not a read operation", req.Op)
+}
+
+// Issues looks up the issues an assign, resolve or ignore names. Short IDs
+// are resolved to the numeric IDs the update endpoint takes.
+func (o *Ops) Issues(ctx context.Context, refs []string) ([]sentry.Issue, error) {
+	issues := make([]sentry.Issue, 0, len(refs))
+	for _, ref := range refs {
+		var issue *sentry.Issue
+		var err error
+		if numericIDRe.MatchString(ref) {
+			issue, err = o.api.GetIssue(ctx, ref)
+		} else {
+			issue, err = o.api.ResolveShortID(ctx, o.org, ref)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up issue %s: %w", ref, err)
+		}
+		issues = append(issues, *issue)
+	}
+	return issues, nil
+}
+
+// RelatedIssues returns the most frequent unresolved issues of the last
+// day reported from hosts named after workload, such as the pods of a
+// deployment, which report their pod name as server_name.
+func (o *Ops) RelatedIssues(ctx context.Context, workload string, limit int) ([]sentry.Issue, error) {
+	issues, _, err := o.api.ListIssues(ctx, o.org, sentry.IssueListOptions{
+		Query:       fmt.Sprintf("is:unresolved server_name:%s*", workload),
+		StatsPeriod: "24h",
+		Sort:        "freq",
+		Limit:       limit,
+	})
+	return issues, err
+}
+
+// issueOptions turns a top or search request into an issue search. Crash
+// questions only count unhandled errors, which is what Sentry's crash-free
+// rates are computed from.
+func issueOptions(req OpRequest) sentry.IssueListOptions {
+	query := []string{"is:unresolved"}
+	if req.Crashes {
+		query = append(query, "error.unhandled:true")
+	}
+	if req.Release != "" {
+		query = append(query, "release:"+req.Release)
+	}
+	if req.Project != "" {
+		query = append(query, "project:"+req.Project)
+	}
+	opts := sentry.IssueListOptions{
+		Query:       strings.Join(query, " "),
+		Environment: req.Environment,
+		StatsPeriod: req.Period,
+		Sort:        "date",
+		Limit:       searchIssuesLimit,
+	}
+	if req.Op == OpTopIssues {
+		opts.Sort = "freq"
+		opts.Limit = topIssuesLimit
+	}
+	return opts
+}
+
+// describeSearch heads an issue listing with what was searched for
+func describeSearch(req OpRequest, opts sentry.IssueListOptions) string {
+	what := "Unresolved issues"
+	if req.Crashes {
+		what = "Unresolved crashes"
+	}
+	if req.Op == OpTopIssues {
+		what = "Top " + strings.ToLower(what[:1]) + what[1:] + " by events"
+	}
+	period := opts.StatsPeriod
+	if period == "" {
+		period = "14d"
+	}
+	var scope []string
+	if req.Project != "" {
+		scope = append(scope, "project "+req.Project)
+	}
+	if req.Release != "" {
+		scope = append(scope, "release "+req.Release)
+	}
+	if req.Environment != "" {
+		scope = append(scope, "environment "+req.Environment)
+	}
+	out := fmt.Sprintf("%s in the last %s", what, period)
+	if len(scope) > 0 {
+		out += " (" + strings.Join(scope, ", ") + ")"
+	}
+	return out
+}
+
+func (o *Ops) formatIssues(heading string, issues []sentry.Issue) string {
+	if len(issues) == 0 {
+		return fmt.Sprintf("No issues found. Searched: %s.\n", strings.ToLower(heading[:1])+heading[1:])
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s:\n", heading)
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ISSUE\tEVENTS\tUSERS\tLAST SEEN\tPROJECT\tTITLE")
+	for _, i := range issues {
+		project := "-"
+		if i.Project != nil && i.Project.Slug != "" {
+			project = i.Project.Slug
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%d\t%s\t%s\t%s\n", i.ShortID, orDash(i.Count), i.UserCount, o.age(i.LastSeen), project, truncate(i.Title, titleWidth))
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+// releaseHealth reports session outcomes for a release over the last day,
+// defaulting to the project's newest release, with the release's top
+// unresolved issues
+func (o *Ops) releaseHealth(ctx context.Context, req OpRequest) (string, error) {
+	version := req.Release
+	if version == "" {
+		if req.Project == "" {
+			return "", fmt.Errorf("name the release or the Sentry project, e.g. \"release health of project backend\"")
+		}
+		releases, err := o.api.ListReleases(ctx, o.org, req.Project)
+		if err != nil {
+			return "", err
+		}
+		if len(releases) == 0 {
+			return "", fmt.Errorf("project %s has no releases", req.Project)
+		}
+		version = releases[0].Version
+	}
+	health, err := o.api.GetReleaseHealth(ctx, o.org, req.Project, version)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Release %s, last 24h:\n", version)
+	counts := sessionCounts(health)
+	var total float64
+	for _, n := range counts {
+		total += n
+	}
+	if total == 0 {
+		sb.WriteString("  No sessions recorded. Release health needs a Sentry SDK with session tracking enabled.\n")
+	} else {
+		crashed := counts["crashed"]
+		fmt.Fprintf(&sb, "  Sessions:   %.0f\n", total)
+		fmt.Fprintf(&sb, "  Crash-free: %.2f%%\n", (1-crashed/total)*100)
+		statuses := make([]string, 0, len(counts))
+		for status := range counts {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			fmt.Fprintf(&sb, "  %-11s %.0f\n", status+":", counts[status])
+		}
+	}
+
+	top := OpRequest{Op: OpTopIssues, Project: req.Project, Release: version, Environment: req.Environment, Period: "24h"}
+	opts := issueOptions(top)
+	opts.Limit = 5
+	issues, _, err := o.api.ListIssues(ctx, o.org, opts)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString("\n")
+	sb.WriteString(o.formatIssues(describeSearch(top, opts), issues))
+	return sb.String(), nil
+}
+
+// sessionCounts sums sum(session) by session.status
+func sessionCounts(health *sentry.SessionsResponse) map[string]float64 {
+	counts := map[string]float64{}
+	if health == nil {
+		return counts
+	}
+	for _, g := range health.Groups {
+		status := g.By["session.status"]
+		if status == "" {
+			continue
+		}
+		counts[status] += g.Totals["sum(session)"]
+	}
+	return counts
+}
+
+// age renders a timestamp as a coarse age
+func (o *Ops) age(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := o.now.Sub(t)
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
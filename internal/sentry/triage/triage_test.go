@@ -0,0 +1,216 @@
+package triage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/sentry"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// fakeAPI serves a fixed set of issues and records the searches run
+type fakeAPI struct {
+	issues   []sentry.Issue
+	searches []sentry.IssueListOptions
+	health   *sentry.SessionsResponse
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{issues: []sentry.Issue{
+		{ID: "4012345", ShortID: "BACKEND-42", Title: "TypeError: cannot read properties of undefined", Count: "1520", UserCount: 310,
+			LastSeen: testNow.Add(-2 * time.Hour), Project: &sentry.Project{Slug: "backend"}},
+		{ID: "4012399", ShortID: "BACKEND-57", Title: "ConnectionResetError", Count: "88", UserCount: 12,
+			LastSeen: testNow.Add(-3 * 24 * time.Hour), Project: &sentry.Project{Slug: "backend"}},
+	}}
+}
+
+func (f *fakeAPI) ListIssues(ctx context.Context, orgSlug string, opts sentry.IssueListOptions) ([]sentry.Issue, string, error) {
+	f.searches = append(f.searches, opts)
+	return f.issues, "", nil
+}
+
+func (f *fakeAPI) GetIssue(ctx context.Context, issueID string) (*sentry.Issue, error) {
+	for _, i := range f.issues {
+		if i.ID == issueID {
+			return &i, nil
+		}
+	}
+	return nil, fmt.Errorf("issue %s not found", issueID)
+}
+
+func (f *fakeAPI) ResolveShortID(ctx context.Context, orgSlug, shortID string) (*sentry.Issue, error) {
+	for _, i := range f.issues {
+		if i.ShortID == shortID {
+			return &i, nil
+		}
+	}
+	return nil, fmt.Errorf("short ID %s not found", shortID)
+}
+
+func (f *fakeAPI) ListReleases(ctx context.Context, orgSlug, projectSlug string) ([]sentry.Release, error) {
+	return []sentry.Release{{Version: "backend@2.4.1"}, {Version: "backend@2.4.0"}}, nil
+}
+
+func (f *fakeAPI) GetReleaseHealth(ctx context.Context, orgSlug, projectSlug, version string) (*sentry.SessionsResponse, error) {
+	return f.health, nil
+}
+
+func TestParseOpRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     OpRequest
+	}{
+		{"what are the top crashers this week", OpRequest{Op: OpTopIssues, Period: "7d", Crashes: true}},
+		{"show sentry issues in release 2.4.1 on production", OpRequest{Op: OpSearchIssues, Release: "2.4.1", Environment: "production"}},
+		{"list unresolved sentry errors for project backend today", OpRequest{Op: OpSearchIssues, Project: "backend", Period: "24h"}},
+		{"what is the release health of backend@2.4.1 --release backend@2.4.1", OpRequest{Op: OpReleaseHealth, Release: "backend@2.4.1"}},
+		{"crash-free rate for project backend", OpRequest{Op: OpReleaseHealth, Project: "backend", Crashes: true}},
+		{"resolve sentry issues BACKEND-42 and BACKEND-57", OpRequest{Op: OpResolve, Issues: []string{"BACKEND-42", "BACKEND-57"}}},
+		{"ignore sentry issue 4012345", OpRequest{Op: OpIgnore, Issues: []string{"4012345"}}},
+		{"assign BACKEND-42 to jane@example.com", OpRequest{Op: OpAssign, Issues: []string{"BACKEND-42"}, Assignee: "jane@example.com"}},
+	}
+	for _, tt := range tests {
+		if got := ParseOpRequest(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseOpRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+
+	for _, q := range []string{
+		"how do I resolve sentry issues from the cli",
+		"why is the checkout page slow",
+		"resolve the dns name api.example.com",
+	} {
+		if got := ParseOpRequest(q); got.Op != "" {
+			t.Errorf("ParseOpRequest(%q) = %+v, want no op", q, got)
+		}
+	}
+}
+
+func TestIsTriageQuestion(t *testing.T) {
+	for q, want := range map[string]bool{
+		"what are the top crashers this week":    true,
+		"release health of backend@2.4.1":        true,
+		"list sentry issues":                     true,
+		"which lambda functions have errors":     false,
+		"top pods by memory in the prod cluster": false,
+	} {
+		if got := IsTriageQuestion(q); got != want {
+			t.Errorf("IsTriageQuestion(%q) = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func TestReadTopCrashers(t *testing.T) {
+	api := newFakeAPI()
+	out, err := NewOps(api, "acme", testNow).Read(context.Background(), ParseOpRequest("what are the top crashers this week in production"))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	got := api.searches[0]
+	want := sentry.IssueListOptions{Query: "is:unresolved error.unhandled:true", Environment: "production", StatsPeriod: "7d", Sort: "freq", Limit: topIssuesLimit}
+	if got != want {
+		t.Errorf("search = %+v, want %+v", got, want)
+	}
+	for _, s := range []string{"Top unresolved crashes by events in the last 7d (environment production)", "BACKEND-42", "1520", "2h", "3d", "ConnectionResetError"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("output missing %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestReadSearchByRelease(t *testing.T) {
+	api := newFakeAPI()
+	api.issues = nil
+	out, err := NewOps(api, "acme", testNow).Read(context.Background(), ParseOpRequest("show sentry issues in release 2.4.1 for project backend"))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if q := api.searches[0].Query; q != "is:unresolved release:2.4.1 project:backend" {
+		t.Errorf("query = %q", q)
+	}
+	if !strings.Contains(out, "No issues found") || !strings.Contains(out, "release 2.4.1") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestReadReleaseHealth(t *testing.T) {
+	api := newFakeAPI()
+	api.health = &sentry.SessionsResponse{Groups: []sentry.SessionGroup{
+		{By: map[string]string{"session.status": "healthy"}, Totals: map[string]float64{"sum(session)": 9900}},
+		{By: map[string]string{"session.status": "crashed"}, Totals: map[string]float64{"sum(session)": 50}},
+		{By: map[string]string{"session.status": "errored"}, Totals: map[string]float64{"sum(session)": 50}},
+	}}
+	out, err := NewOps(api, "acme", testNow).Read(context.Background(), OpRequest{Op: OpReleaseHealth, Project: "backend"})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	for _, s := range []string{"Release backend@2.4.1", "Sessions:   10000", "Crash-free: 99.50%", "crashed:", "BACKEND-42"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("output missing %q:\n%s", s, out)
+		}
+	}
+	if q := api.searches[0].Query; q != "is:unresolved release:backend@2.4.1 project:backend" {
+		t.Errorf("release issues query = %q", q)
+	}
+
+	if _, err := NewOps(api, "acme", testNow).Read(context.Background(), OpRequest{Op: OpReleaseHealth}); err == nil {
+		t.Error("expected an error without a release or project")
+	}
+}
+
+func TestRelatedIssues(t *testing.T) {
+	api := newFakeAPI()
+	if _, err := NewOps(api, "acme", testNow).RelatedIssues(context.Background(), "checkout-api", 5); err != nil {
+		t.Fatalf("RelatedIssues: %v", err)
+	}
+	want := sentry.IssueListOptions{Query: "is:unresolved server_name:checkout-api*", StatsPeriod: "24h", Sort: "freq", Limit: 5}
+	if api.searches[0] != want {
+		t.Errorf("search = %+v, want %+v", api.searches[0], want)
+	}
+}
+
+func TestBuildOpPlan(t *testing.T) {
+	ops := NewOps(newFakeAPI(), "acme", testNow)
+	req := ParseOpRequest("resolve sentry issues BACKEND-42 and BACKEND-57")
+	issues, err := ops.Issues(context.Background(), []string{"BACKEND-42", "4012399"})
+	if err != nil {
+		t.Fatalf("Issues: %v", err)
+	}
+	p, err := BuildOpPlan(req, "acme", issues, "q", testNow)
+	if err != nil {
+		t.Fatalf("BuildOpPlan: %v", err)
+	}
+	if p.Plan.Provider != "sentry" || len(p.Plan.Commands) != 2 {
+		t.Fatalf("plan = %+v", p.Plan)
+	}
+	want := []string{"sentry-api", "PUT", "/organizations/acme/issues/?id=4012345", `{"status":"resolved"}`}
+	if got := p.Plan.Commands[0].Args; !reflect.DeepEqual(got, want) {
+		t.Errorf("args = %v, want %v", got, want)
+	}
+	if p.Plan.Summary != "Resolve BACKEND-42, BACKEND-57 in acme" {
+		t.Errorf("summary = %q", p.Plan.Summary)
+	}
+
+	assign := ParseOpRequest("assign sentry issue BACKEND-42 to jane")
+	p, err = BuildOpPlan(assign, "acme", issues[:1], "q", testNow)
+	if err != nil {
+		t.Fatalf("BuildOpPlan assign: %v", err)
+	}
+	var body sentry.IssueUpdate
+	if err := json.Unmarshal([]byte(p.Plan.Commands[0].Args[3]), &body); err != nil || body.AssignedTo != "jane" || body.Status != "" {
+		t.Errorf("assign body = %s (%v)", p.Plan.Commands[0].Args[3], err)
+	}
+
+	if _, err := BuildOpPlan(OpRequest{Op: OpAssign}, "acme", issues, "q", testNow); err == nil {
+		t.Error("expected an error without an assignee")
+	}
+	if _, err := BuildOpPlan(OpRequest{Op: OpResolve}, "acme", nil, "q", testNow); err == nil {
+		t.Error("expected an error without issues")
+	}
+}
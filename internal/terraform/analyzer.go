@@ -19,6 +19,7 @@ const (
 	defaultMaxOutputLines = 80
 	stalePlanAge          = 24 * time.Hour
 	staleLocalStateAge    = 30 * 24 * time.Hour
+	planTimeout           = 90 * time.Second
 )
 
 type AnalysisOptions struct {
@@ -214,7 +215,7 @@ func (c *Client) normalPlan(ctx context.Context, binary string, maxLines int) *D
 }
 
 func (c *Client) planReport(ctx context.Context, binary string, args []string, maxLines int) *DriftReport {
-	output, exitCode, err := runTerraformCommandDetailed(ctx, c.path, binary, 90*time.Second, args...)
+	output, exitCode, err := runTerraformCommandDetailed(ctx, c.path, binary, planTimeout, args...)
 	report := &DriftReport{
 		Checked:  true,
 		ExitCode: exitCode,
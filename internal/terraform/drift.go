@@ -0,0 +1,205 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PlanChange is one resource a plan would touch, with the plan's wording
+// for what happens to it
+type PlanChange struct {
+	Address string `json:"address"`
+	Action  string `json:"action"`
+}
+
+// LiveResource is one resource found in the cloud account
+type LiveResource struct {
+	ARN     string `json:"arn"`
+	Service string `json:"service"`
+	Type    string `json:"type,omitempty"`
+	ID      string `json:"id"`
+}
+
+// DriftCheck is the outcome of a detailed-exitcode plan, plus the live
+// resources state does not account for
+type DriftCheck struct {
+	Workspace   string         `json:"workspace"`
+	Path        string         `json:"path"`
+	Tool        string         `json:"tool"`
+	Plan        *DriftReport   `json:"plan"`
+	Changes     []PlanChange   `json:"changes,omitempty"`
+	Managed     int            `json:"managed"`
+	LiveChecked bool           `json:"liveChecked"`
+	LiveCount   int            `json:"liveCount,omitempty"`
+	LiveError   string         `json:"liveError,omitempty"`
+	Unmanaged   []LiveResource `json:"unmanaged,omitempty"`
+}
+
+// LiveInventory lists the resources that exist in the cloud account
+type LiveInventory func(ctx context.Context) ([]LiveResource, error)
+
+// planChangeRe matches the resource headers of plan output, such as
+// "# aws_instance.web will be updated in-place" or "# aws_s3_bucket.logs has changed"
+var planChangeRe = regexp.MustCompile(`^#\s+(\S+)\s+(will be .+|must be replaced|has changed|has been deleted|is tainted.*)$`)
+
+// CheckDrift runs `plan -detailed-exitcode` and, when live is set,
+// compares the resources state manages against live inventory. Live
+// inventory failing is reported on the check rather than failing it.
+func (c *Client) CheckDrift(ctx context.Context, live LiveInventory, maxLines int) (*DriftCheck, error) {
+	if maxLines <= 0 {
+		maxLines = defaultMaxOutputLines
+	}
+	args := []string{"plan", "-detailed-exitcode", "-no-color", "-compact-warnings", "-input=false"}
+	output, exitCode, err := runTerraformCommandDetailed(ctx, c.path, c.binary, planTimeout, args...)
+	if err != nil && exitCode != 2 {
+		return nil, err
+	}
+	check := &DriftCheck{
+		Workspace: c.workspace,
+		Path:      c.path,
+		Tool:      displayToolName(c.binary),
+		Plan: &DriftReport{
+			Checked:    true,
+			HasChanges: exitCode == 2,
+			ExitCode:   exitCode,
+			Command:    c.binary + " " + strings.Join(args, " "),
+			Summary:    summarizePlanOutput(output),
+			Output:     limitStrings(nonEmptyLines(output), maxLines),
+		},
+		Changes: planChanges(output),
+	}
+	if live == nil {
+		return check, nil
+	}
+
+	managed, err := c.ManagedResources(ctx)
+	if err != nil {
+		check.LiveError = err.Error()
+		return check, nil
+	}
+	check.Managed = len(managed)
+	resources, err := live(ctx)
+	if err != nil {
+		check.LiveError = err.Error()
+		return check, nil
+	}
+	check.LiveChecked = true
+	check.LiveCount = len(resources)
+	check.Unmanaged = Unmanaged(managed, resources)
+	return check, nil
+}
+
+func planChanges(output string) []PlanChange {
+	var changes []PlanChange
+	seen := make(map[string]bool)
+	for _, line := range nonEmptyLines(output) {
+		match := planChangeRe.FindStringSubmatch(line)
+		if match == nil || seen[match[1]+match[2]] {
+			continue
+		}
+		seen[match[1]+match[2]] = true
+		changes = append(changes, PlanChange{Address: match[1], Action: match[2]})
+	}
+	return changes
+}
+
+// Unmanaged returns the live resources no managed resource accounts for,
+// matching on ARN first and then on the ID in the ARN's resource part
+func Unmanaged(managed []ManagedResource, live []LiveResource) []LiveResource {
+	arns := make(map[string]bool, len(managed))
+	ids := make(map[string]bool, len(managed))
+	for _, r := range managed {
+		if r.ARN != "" {
+			arns[strings.TrimSuffix(r.ARN, ":*")] = true
+		}
+		if r.ID != "" {
+			ids[r.ID] = true
+		}
+	}
+	var unmanaged []LiveResource
+	for _, r := range live {
+		if arns[r.ARN] || (r.ID != "" && ids[r.ID]) || ids[r.ARN] {
+			continue
+		}
+		unmanaged = append(unmanaged, r)
+	}
+	sort.Slice(unmanaged, func(i, j int) bool { return unmanaged[i].ARN < unmanaged[j].ARN })
+	return unmanaged
+}
+
+// ParseTaggedResources reads the output of
+// `aws resourcegroupstaggingapi get-resources --output json`
+func ParseTaggedResources(data []byte) ([]LiveResource, error) {
+	var out struct {
+		ResourceTagMappingList []struct {
+			ResourceARN string `json:"ResourceARN"`
+		} `json:"ResourceTagMappingList"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse tagged resources: %w", err)
+	}
+	resources := make([]LiveResource, 0, len(out.ResourceTagMappingList))
+	for _, m := range out.ResourceTagMappingList {
+		if r, ok := ParseARN(m.ResourceARN); ok {
+			resources = append(resources, r)
+		}
+	}
+	return resources, nil
+}
+
+// ParseARN splits an ARN into its service, resource type and ID. Resources
+// are written type/id, type:id or, as for S3 buckets, just the ID.
+func ParseARN(arn string) (LiveResource, bool) {
+	parts := strings.SplitN(strings.TrimSpace(arn), ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] == "" || parts[5] == "" {
+		return LiveResource{}, false
+	}
+	r := LiveResource{ARN: strings.TrimSpace(arn), Service: parts[2], ID: parts[5]}
+	if i := strings.IndexAny(parts[5], "/:"); i > 0 {
+		r.Type = parts[5][:i]
+		r.ID = parts[5][i+1:]
+	}
+	return r, true
+}
+
+// Format renders the plan's verdict, the resources it would change and the
+// live resources outside state
+func (d *DriftCheck) Format() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s drift check for workspace %s (%s)\n", d.Tool, fallbackViewText(d.Workspace, "local"), d.Path)
+	fmt.Fprintf(&sb, "Command: %s (exit code %d)\n", d.Plan.Command, d.Plan.ExitCode)
+	if d.Plan.HasChanges {
+		sb.WriteString("Drift: yes, the plan is not empty\n")
+	} else {
+		sb.WriteString("Drift: none, infrastructure matches the configuration\n")
+	}
+	for _, line := range d.Plan.Summary {
+		fmt.Fprintf(&sb, "  %s\n", line)
+	}
+	if len(d.Changes) > 0 {
+		fmt.Fprintf(&sb, "\nChanged resources (%d):\n", len(d.Changes))
+		for _, change := range d.Changes {
+			fmt.Fprintf(&sb, "  - %s %s\n", change.Address, change.Action)
+		}
+	}
+
+	sb.WriteString("\nAWS resources managed outside Terraform (tagged resources in the current region):\n")
+	switch {
+	case d.LiveError != "":
+		fmt.Fprintf(&sb, "  not checked: %s\n", d.LiveError)
+	case !d.LiveChecked:
+		sb.WriteString("  not checked\n")
+	case len(d.Unmanaged) == 0:
+		fmt.Fprintf(&sb, "  none; all %d live resource(s) are in state (%d managed)\n", d.LiveCount, d.Managed)
+	default:
+		fmt.Fprintf(&sb, "  %d of %d live resource(s) are not in state (%d managed):\n", len(d.Unmanaged), d.LiveCount, d.Managed)
+		for _, r := range d.Unmanaged {
+			fmt.Fprintf(&sb, "  - %s\n", r.ARN)
+		}
+	}
+	return sb.String()
+}
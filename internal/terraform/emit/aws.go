@@ -0,0 +1,390 @@
+package emit
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// args is a CLI command's flags and positional arguments
+type args struct {
+	positional []string
+	flags      map[string][]string
+}
+
+// parseArgs reads --flag value, --flag=value and --flag v1 v2 forms. Flags
+// without a value, such as --preemptible, are recorded with no values.
+func parseArgs(argv []string) args {
+	a := args{flags: map[string][]string{}}
+	current := ""
+	for _, arg := range argv[1:] {
+		if strings.HasPrefix(arg, "--") {
+			name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+			current = name
+			if _, ok := a.flags[name]; !ok {
+				a.flags[name] = nil
+			}
+			if hasValue {
+				a.flags[name] = append(a.flags[name], value)
+				current = ""
+			}
+			continue
+		}
+		if current != "" {
+			a.flags[current] = append(a.flags[current], arg)
+			continue
+		}
+		a.positional = append(a.positional, arg)
+	}
+	return a
+}
+
+func (a args) get(name string) string {
+	if v := a.flags[name]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (a args) has(name string) bool {
+	_, ok := a.flags[name]
+	return ok
+}
+
+func (a args) int(name string) any {
+	n, err := strconv.Atoi(a.get(name))
+	if err != nil {
+		return nil
+	}
+	return n
+}
+
+// handlers render one plan command each, keyed by handlerKey. A handler
+// returning nil leaves the command in Module.Skipped.
+var handlers map[string]func(e *emitter, a args) *block
+
+func init() {
+	handlers = map[string]func(e *emitter, a args) *block{
+		"aws s3 mb":                                s3MakeBucket,
+		"aws s3api create-bucket":                  s3CreateBucket,
+		"aws ec2 create-vpc":                       ec2CreateVPC,
+		"aws ec2 create-subnet":                    ec2CreateSubnet,
+		"aws ec2 create-internet-gateway":          ec2CreateInternetGateway,
+		"aws ec2 attach-internet-gateway":          ec2AttachInternetGateway,
+		"aws ec2 create-security-group":            ec2CreateSecurityGroup,
+		"aws ec2 authorize-security-group-ingress": ec2AuthorizeIngress,
+		"aws ec2 run-instances":                    ec2RunInstances,
+		"aws sqs create-queue":                     sqsCreateQueue,
+		"aws sns create-topic":                     snsCreateTopic,
+		"aws ecr create-repository":                ecrCreateRepository,
+		"aws logs create-log-group":                logsCreateLogGroup,
+		"aws dynamodb create-table":                dynamodbCreateTable,
+		"aws iam create-role":                      iamCreateRole,
+		"aws iam attach-role-policy":               iamAttachRolePolicy,
+		"aws lambda create-function":               lambdaCreateFunction,
+		"aws rds create-db-instance":               rdsCreateDBInstance,
+		"aws eks create-cluster":                   eksCreateCluster,
+		"eksctl create cluster":                    eksctlCreateCluster,
+		"gcloud container clusters create":         gkeCreateCluster,
+		"gcloud container clusters create-auto":    gkeCreateAutopilotCluster,
+	}
+}
+
+func s3MakeBucket(e *emitter, a args) *block {
+	bucket := strings.TrimSuffix(strings.TrimPrefix(a.positionalAfter("mb"), "s3://"), "/")
+	if bucket == "" {
+		return nil
+	}
+	return e.resource("aws_s3_bucket", bucket).set("bucket", e.value(bucket))
+}
+
+func s3CreateBucket(e *emitter, a args) *block {
+	bucket := a.get("bucket")
+	if bucket == "" {
+		return nil
+	}
+	return e.resource("aws_s3_bucket", bucket).set("bucket", e.value(bucket))
+}
+
+// tagSpecRe reads Key=...,Value=... pairs from --tag-specifications
+var tagSpecRe = regexp.MustCompile(`Key=([^,}\]]+),Value=([^,}\]]*)`)
+
+func (e *emitter) tags(a args) (object, string) {
+	var tags object
+	name := ""
+	for _, spec := range a.flags["tag-specifications"] {
+		for _, m := range tagSpecRe.FindAllStringSubmatch(spec, -1) {
+			tags = append(tags, attribute{m[1], e.value(m[2])})
+			if m[1] == "Name" {
+				name = m[2]
+			}
+		}
+	}
+	return tags, name
+}
+
+func ec2CreateVPC(e *emitter, a args) *block {
+	tags, name := e.tags(a)
+	return e.resource("aws_vpc", firstNonEmpty(name, "main")).
+		set("cidr_block", e.value(a.get("cidr-block"))).
+		set("tags", tags)
+}
+
+func ec2CreateSubnet(e *emitter, a args) *block {
+	tags, name := e.tags(a)
+	return e.resource("aws_subnet", firstNonEmpty(name, a.get("availability-zone"), "subnet")).
+		set("vpc_id", e.value(a.get("vpc-id"))).
+		set("cidr_block", e.value(a.get("cidr-block"))).
+		set("availability_zone", e.value(a.get("availability-zone"))).
+		set("tags", tags)
+}
+
+func ec2CreateInternetGateway(e *emitter, a args) *block {
+	tags, name := e.tags(a)
+	return e.resource("aws_internet_gateway", firstNonEmpty(name, "main")).set("tags", tags)
+}
+
+// ec2AttachInternetGateway sets vpc_id on a gateway the plan created; a
+// gateway created elsewhere gets an attachment resource
+func ec2AttachInternetGateway(e *emitter, a args) *block {
+	gateway := e.value(a.get("internet-gateway-id"))
+	if ref, ok := gateway.(expr); ok {
+		for _, b := range e.blocks {
+			if b.kind == "resource" && b.labels[0] == "aws_internet_gateway" && string(ref) == b.ref("$.InternetGatewayId") {
+				b.set("vpc_id", e.value(a.get("vpc-id")))
+				return b
+			}
+		}
+	}
+	return e.resource("aws_internet_gateway_attachment", "main").
+		set("internet_gateway_id", gateway).
+		set("vpc_id", e.value(a.get("vpc-id")))
+}
+
+func ec2CreateSecurityGroup(e *emitter, a args) *block {
+	name := a.get("group-name")
+	return e.resource("aws_security_group", name).
+		set("name", e.value(name)).
+		set("description", e.value(a.get("description"))).
+		set("vpc_id", e.value(a.get("vpc-id")))
+}
+
+var (
+	ipProtocolRe = regexp.MustCompile(`IpProtocol=([^,}\]]+)`)
+	fromPortRe   = regexp.MustCompile(`FromPort=(-?\d+)`)
+	toPortRe     = regexp.MustCompile(`ToPort=(-?\d+)`)
+	cidrIPRe     = regexp.MustCompile(`CidrIp=([^,}\]]+)`)
+	groupIDRe    = regexp.MustCompile(`GroupId=([^,}\]]+)`)
+)
+
+// ec2AuthorizeIngress handles the --protocol/--port/--cidr form and a
+// single rule given with --ip-permissions
+func ec2AuthorizeIngress(e *emitter, a args) *block {
+	protocol, from, to, cidr, source := a.get("protocol"), "", "", a.get("cidr"), a.get("source-group")
+	if port := a.get("port"); port != "" {
+		from, to, _ = strings.Cut(port, "-")
+		if to == "" {
+			to = from
+		}
+	}
+	if perms := a.get("ip-permissions"); perms != "" {
+		protocol = submatch(ipProtocolRe, perms)
+		from, to = submatch(fromPortRe, perms), submatch(toPortRe, perms)
+		cidr, source = submatch(cidrIPRe, perms), submatch(groupIDRe, perms)
+	}
+	if protocol == "" || (cidr == "" && source == "") {
+		return nil
+	}
+	b := e.resource("aws_vpc_security_group_ingress_rule", "ingress_"+protocol+"_"+from).
+		set("security_group_id", e.value(a.get("group-id"))).
+		set("ip_protocol", protocol)
+	if protocol != "-1" && protocol != "all" {
+		b.set("from_port", atoi(from)).set("to_port", atoi(to))
+	}
+	return b.set("cidr_ipv4", e.value(cidr)).set("referenced_security_group_id", e.value(source))
+}
+
+func ec2RunInstances(e *emitter, a args) *block {
+	tags, name := e.tags(a)
+	b := e.resource("aws_instance", firstNonEmpty(name, "instance")).
+		set("ami", e.value(a.get("image-id"))).
+		set("instance_type", e.value(a.get("instance-type"))).
+		set("subnet_id", e.value(a.get("subnet-id"))).
+		set("vpc_security_group_ids", e.values(a.flags["security-group-ids"])).
+		set("key_name", e.value(a.get("key-name"))).
+		set("iam_instance_profile", e.value(strings.TrimPrefix(a.get("iam-instance-profile"), "Name=")))
+	if count, ok := a.int("count").(int); ok && count > 1 {
+		b.set("count", count)
+	}
+	if userData := a.get("user-data"); strings.HasPrefix(userData, "file://") {
+		b.set("user_data", expr(`file("`+escape(strings.TrimPrefix(userData, "file://"))+`")`))
+	} else {
+		b.set("user_data", e.value(userData))
+	}
+	return b.set("tags", tags)
+}
+
+func sqsCreateQueue(e *emitter, a args) *block {
+	name := a.get("queue-name")
+	return e.resource("aws_sqs_queue", name).set("name", e.value(name))
+}
+
+func snsCreateTopic(e *emitter, a args) *block {
+	name := a.get("name")
+	return e.resource("aws_sns_topic", name).set("name", e.value(name))
+}
+
+func ecrCreateRepository(e *emitter, a args) *block {
+	name := a.get("repository-name")
+	return e.resource("aws_ecr_repository", name).set("name", e.value(name))
+}
+
+func logsCreateLogGroup(e *emitter, a args) *block {
+	name := a.get("log-group-name")
+	return e.resource("aws_cloudwatch_log_group", name).set("name", e.value(name))
+}
+
+var (
+	attributeDefRe = regexp.MustCompile(`AttributeName=([^,\s]+),AttributeType=([SNB])`)
+	keySchemaRe    = regexp.MustCompile(`AttributeName=([^,\s]+),KeyType=(HASH|RANGE)`)
+	readUnitsRe    = regexp.MustCompile(`ReadCapacityUnits=(\d+)`)
+	writeUnitsRe   = regexp.MustCompile(`WriteCapacityUnits=(\d+)`)
+)
+
+func dynamodbCreateTable(e *emitter, a args) *block {
+	name := a.get("table-name")
+	b := e.resource("aws_dynamodb_table", name).
+		set("name", e.value(name)).
+		set("billing_mode", firstNonEmpty(a.get("billing-mode"), "PROVISIONED"))
+	for _, m := range keySchemaRe.FindAllStringSubmatch(strings.Join(a.flags["key-schema"], " "), -1) {
+		key := "hash_key"
+		if m[2] == "RANGE" {
+			key = "range_key"
+		}
+		b.set(key, m[1])
+	}
+	if throughput := a.get("provisioned-throughput"); throughput != "" {
+		b.set("read_capacity", atoi(submatch(readUnitsRe, throughput))).set("write_capacity", atoi(submatch(writeUnitsRe, throughput)))
+	}
+	for _, m := range attributeDefRe.FindAllStringSubmatch(strings.Join(a.flags["attribute-definitions"], " "), -1) {
+		b.add(newBlock("attribute").set("name", m[1]).set("type", m[2]))
+	}
+	return b
+}
+
+// document renders a policy given inline or as file://path
+func (e *emitter) document(doc string) any {
+	if path, ok := strings.CutPrefix(doc, "file://"); ok {
+		return expr(`file("` + escape(path) + `")`)
+	}
+	return e.value(doc)
+}
+
+func iamCreateRole(e *emitter, a args) *block {
+	name := a.get("role-name")
+	return e.resource("aws_iam_role", name).
+		set("name", e.value(name)).
+		set("assume_role_policy", e.document(a.get("assume-role-policy-document"))).
+		set("description", e.value(a.get("description")))
+}
+
+// iamAttachRolePolicy refers to a role the plan created by reference, so
+// Terraform orders the attachment after it
+func iamAttachRolePolicy(e *emitter, a args) *block {
+	role := e.value(a.get("role-name"))
+	for _, b := range e.blocks {
+		if b.kind == "resource" && b.labels[0] == "aws_iam_role" && b.get("name") == role {
+			role = expr(b.ref("$.Role.RoleName"))
+		}
+	}
+	policy := a.get("policy-arn")
+	return e.resource("aws_iam_role_policy_attachment", a.get("role-name")+"_"+policy[strings.LastIndex(policy, "/")+1:]).
+		set("role", role).
+		set("policy_arn", e.value(policy))
+}
+
+func lambdaCreateFunction(e *emitter, a args) *block {
+	name := a.get("function-name")
+	b := e.resource("aws_lambda_function", name).
+		set("function_name", e.value(name)).
+		set("runtime", e.value(a.get("runtime"))).
+		set("handler", e.value(a.get("handler"))).
+		set("role", e.value(a.get("role"))).
+		set("timeout", a.int("timeout")).
+		set("memory_size", a.int("memory-size"))
+	if zip, ok := strings.CutPrefix(a.get("zip-file"), "fileb://"); ok {
+		b.set("filename", zip).set("source_code_hash", expr(`filebase64sha256("`+escape(zip)+`")`))
+	}
+	if code := a.get("code"); strings.Contains(code, "ImageUri=") {
+		b.set("package_type", "Image").set("image_uri", e.value(strings.TrimPrefix(code, "ImageUri=")))
+	}
+	return b
+}
+
+// rdsCreateDBInstance never writes the master password; it becomes a
+// sensitive variable
+func rdsCreateDBInstance(e *emitter, a args) *block {
+	id := a.get("db-instance-identifier")
+	b := e.resource("aws_db_instance", id).
+		set("identifier", e.value(id)).
+		set("engine", e.value(a.get("engine"))).
+		set("engine_version", e.value(a.get("engine-version"))).
+		set("instance_class", e.value(a.get("db-instance-class"))).
+		set("allocated_storage", a.int("allocated-storage")).
+		set("db_name", e.value(a.get("db-name"))).
+		set("username", e.value(a.get("master-username"))).
+		set("vpc_security_group_ids", e.values(a.flags["vpc-security-group-ids"])).
+		set("db_subnet_group_name", e.value(a.get("db-subnet-group-name")))
+	if a.has("manage-master-user-password") {
+		b.set("manage_master_user_password", true)
+	} else if a.get("master-user-password") != "" {
+		b.set("password", e.secret(strings.Trim(labelRe.ReplaceAllString(strings.ToLower(id), "_"), "_")+"_password", "Master password for RDS instance "+id))
+	}
+	if a.has("multi-az") {
+		b.set("multi_az", true)
+	}
+	if a.has("no-publicly-accessible") {
+		b.set("publicly_accessible", false)
+	} else if a.has("publicly-accessible") {
+		b.set("publicly_accessible", true)
+	}
+	return b.set("skip_final_snapshot", false)
+}
+
+var (
+	subnetIDsRe        = regexp.MustCompile(`subnetIds=([^=]+?)(?:,[A-Za-z]+=|$)`)
+	securityGroupIDsRe = regexp.MustCompile(`securityGroupIds=([^=]+?)(?:,[A-Za-z]+=|$)`)
+)
+
+func eksCreateCluster(e *emitter, a args) *block {
+	name := a.get("name")
+	vpcConfig := a.get("resources-vpc-config")
+	subnets := submatch(subnetIDsRe, vpcConfig)
+	if name == "" || subnets == "" {
+		return nil
+	}
+	config := newBlock("vpc_config").set("subnet_ids", e.values([]string{subnets}))
+	if groups := submatch(securityGroupIDsRe, vpcConfig); groups != "" {
+		config.set("security_group_ids", e.values([]string{groups}))
+	}
+	return e.resource("aws_eks_cluster", name).
+		set("name", e.value(name)).
+		set("role_arn", e.value(a.get("role-arn"))).
+		set("version", e.value(a.get("kubernetes-version"))).
+		add(config)
+}
+
+func submatch(re *regexp.Regexp, s string) string {
+	if m := re.FindStringSubmatch(s); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return ""
+}
+
+func atoi(s string) any {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return nil
+	}
+	return n
+}
@@ -0,0 +1,123 @@
+package emit
+
+import (
+	"strings"
+)
+
+const (
+	vpcModuleSource  = "terraform-aws-modules/vpc/aws"
+	vpcModuleVersion = "~> 5.0"
+	eksModuleSource  = "terraform-aws-modules/eks/aws"
+	eksModuleVersion = "~> 20.0"
+)
+
+// eksctlCreateCluster renders what eksctl builds, a VPC and a cluster with
+// a managed node group, using the community VPC and EKS modules
+func eksctlCreateCluster(e *emitter, a args) *block {
+	name := firstNonEmpty(a.get("name"), a.positionalAfter("cluster"))
+	if name == "" {
+		return nil
+	}
+	nodes, _ := atoi(firstNonEmpty(a.get("nodes"), "2")).(int)
+	minNodes, ok := atoi(a.get("nodes-min")).(int)
+	if !ok {
+		minNodes = nodes
+	}
+	maxNodes, ok := atoi(a.get("nodes-max")).(int)
+	if !ok {
+		maxNodes = nodes
+	}
+
+	var azs any = e.values(a.flags["zones"])
+	if len(a.flags["zones"]) == 0 {
+		zones := e.data("aws_availability_zones", "available").set("state", "available")
+		azs = expr("slice(data." + strings.Join(zones.labels, ".") + ".names, 0, 3)")
+	}
+	vpc := e.module(name+"_vpc", vpcModuleSource, vpcModuleVersion).
+		set("name", e.value(name+"-vpc")).
+		set("cidr", "10.0.0.0/16").
+		set("azs", azs).
+		set("private_subnets", []any{"10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"}).
+		set("public_subnets", []any{"10.0.101.0/24", "10.0.102.0/24", "10.0.103.0/24"}).
+		set("enable_nat_gateway", true).
+		set("single_nat_gateway", true).
+		set("public_subnet_tags", object{{"kubernetes.io/role/elb", "1"}}).
+		set("private_subnet_tags", object{{"kubernetes.io/role/internal-elb", "1"}})
+
+	vpcAddress := "module." + vpc.labels[0]
+	cluster := e.module(name, eksModuleSource, eksModuleVersion).
+		set("cluster_name", e.value(name)).
+		set("cluster_version", e.value(a.get("version"))).
+		set("vpc_id", expr(vpcAddress+".vpc_id")).
+		set("subnet_ids", expr(vpcAddress+".private_subnets")).
+		set("cluster_endpoint_public_access", true).
+		set("enable_cluster_creator_admin_permissions", true).
+		set("eks_managed_node_groups", object{{"default", object{
+			{"instance_types", []any{e.value(firstNonEmpty(a.get("node-type"), "m5.large"))}},
+			{"min_size", minNodes},
+			{"max_size", maxNodes},
+			{"desired_size", nodes},
+		}}})
+	cluster.output = "cluster_endpoint"
+	e.providers["aws"] = true
+	return cluster
+}
+
+// gkeCreateCluster renders a Standard cluster. The default node pool is
+// replaced by a separately managed one, as the google provider recommends.
+func gkeCreateCluster(e *emitter, a args) *block {
+	name := a.positionalAfter("create")
+	if name == "" {
+		return nil
+	}
+	location := e.value(firstNonEmpty(a.get("region"), a.get("zone")))
+	cluster := e.resource("google_container_cluster", name).
+		set("name", e.value(name)).
+		set("location", location).
+		set("min_master_version", e.value(a.get("cluster-version"))).
+		set("remove_default_node_pool", true).
+		set("initial_node_count", 1)
+	if channel := a.get("release-channel"); channel != "" {
+		cluster.add(newBlock("release_channel").set("channel", strings.ToUpper(channel)))
+	}
+	if a.has("enable-autoprovisioning") {
+		autoscaling := newBlock("cluster_autoscaling").set("enabled", true)
+		autoscaling.add(newBlock("resource_limits").set("resource_type", "cpu").set("maximum", a.int("max-cpu")))
+		autoscaling.add(newBlock("resource_limits").set("resource_type", "memory").set("maximum", a.int("max-memory")))
+		cluster.add(autoscaling)
+	}
+
+	nodeConfig := newBlock("node_config").set("machine_type", e.value(a.get("machine-type")))
+	if a.has("preemptible") {
+		nodeConfig.set("preemptible", true)
+	}
+	pool := e.resource("google_container_node_pool", name+"_nodes").
+		set("name", e.value(name+"-nodes")).
+		set("cluster", expr(cluster.ref("$.name"))).
+		set("location", location).
+		set("node_count", atoi(firstNonEmpty(a.get("num-nodes"), "3")))
+	pool.add(nodeConfig)
+	return cluster
+}
+
+func gkeCreateAutopilotCluster(e *emitter, a args) *block {
+	name := a.positionalAfter("create-auto")
+	if name == "" {
+		return nil
+	}
+	return e.resource("google_container_cluster", name).
+		set("name", e.value(name)).
+		set("location", e.value(a.get("region"))).
+		set("enable_autopilot", true)
+}
+
+// positionalAfter returns the positional argument following word, such as
+// the cluster name in "container clusters create NAME"
+func (a args) positionalAfter(word string) string {
+	for i, arg := range a.positional {
+		if arg == word && i+1 < len(a.positional) {
+			return a.positional[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,437 @@
+// Package emit renders maker and K8s cluster plans as a Terraform module
+// instead of running their CLI commands, so teams can adopt the change
+// through their existing IaC pipeline.
+package emit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// Options controls how a plan is rendered
+type Options struct {
+	// Region is the default for var.region when no command names one
+	Region string
+}
+
+// Module is a rendered Terraform module
+type Module struct {
+	// Files maps file names (main.tf, variables.tf, ...) to contents
+	Files map[string]string
+	// Resources counts the resource and module blocks written
+	Resources int
+	// Skipped lists plan commands with no Terraform form
+	Skipped []string
+}
+
+// placeholderRe matches the <NAME> and ${NAME} bindings plans pass
+// between commands
+var placeholderRe = regexp.MustCompile(`<([A-Z][A-Z0-9_]*)>|\$\{([A-Z][A-Z0-9_]*)\}`)
+
+type variable struct {
+	name        string
+	description string
+	def         string
+	sensitive   bool
+}
+
+type emitter struct {
+	opts      Options
+	providers map[string]bool
+	blocks    []*block
+	labels    map[string]bool
+	// refs maps a plan binding to the expression of the resource producing it
+	refs map[string]string
+	// lookups records which read-only command produced a binding
+	lookups   map[string]string
+	variables map[string]*variable
+	region    string
+	project   string
+	skipped   []string
+}
+
+// Render converts the commands of plan into a Terraform module. Commands
+// that only read state are dropped, values they looked up become input
+// variables, and changes with no Terraform form are reported in Skipped.
+func Render(plan *maker.Plan, opts Options) (*Module, error) {
+	if plan == nil || len(plan.Commands) == 0 {
+		return nil, errors.New("plan has no commands to render")
+	}
+	e := &emitter{
+		opts:      opts,
+		providers: map[string]bool{},
+		labels:    map[string]bool{},
+		refs:      map[string]string{},
+		lookups:   map[string]string{},
+		variables: map[string]*variable{},
+	}
+	for _, cmd := range plan.Commands {
+		e.command(cmd)
+	}
+	resources := 0
+	for _, b := range e.blocks {
+		if b.kind != "data" {
+			resources++
+		}
+	}
+	if resources == 0 {
+		return nil, fmt.Errorf("plan has no commands that can be rendered as Terraform (skipped: %s)", strings.Join(e.skipped, "; "))
+	}
+	// Provider blocks declare the region and project variables, so they
+	// are rendered before the variables
+	files := map[string]string{}
+	files["main.tf"] = e.main(plan)
+	files["versions.tf"] = e.versions()
+	files["providers.tf"] = e.providerBlocks()
+	files["variables.tf"] = e.variableBlocks()
+	files["outputs.tf"] = e.outputs()
+	return &Module{
+		Files:     files,
+		Resources: resources,
+		Skipped:   e.skipped,
+	}, nil
+}
+
+// Write saves the module's files in dir, refusing to overwrite any
+func (m *Module) Write(dir string) ([]string, error) {
+	names := make([]string, 0, len(m.Files))
+	for name := range m.Files {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("%s already exists; choose an empty --emit-dir", path)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(m.Files[name]), 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func (e *emitter) command(cmd maker.Command) {
+	if len(cmd.Args) == 0 {
+		return
+	}
+	a := parseArgs(cmd.Args)
+	if e.region == "" {
+		e.region = a.get("region")
+	}
+	if e.project == "" && cmd.Args[0] == "gcloud" {
+		e.project = a.get("project")
+	}
+	if isReadOnly(cmd.Args) {
+		for name := range cmd.Produces {
+			e.lookups[name] = strings.Join(cmd.Args, " ")
+		}
+		return
+	}
+	handler, ok := handlers[handlerKey(cmd.Args)]
+	if !ok {
+		e.skip(cmd.Args)
+		return
+	}
+	b := handler(e, a)
+	if b == nil {
+		e.skip(cmd.Args)
+		return
+	}
+	for name, path := range cmd.Produces {
+		e.refs[name] = b.ref(path)
+	}
+}
+
+func (e *emitter) skip(args []string) {
+	e.skipped = append(e.skipped, strings.Join(args, " "))
+}
+
+// handlerKey is the tool and the words naming the operation, such as
+// "aws ec2 create-vpc" or "gcloud container clusters create"
+func handlerKey(args []string) string {
+	words := []string{args[0]}
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "-") || len(words) == 4 {
+			break
+		}
+		words = append(words, arg)
+		if _, ok := handlers[strings.Join(words, " ")]; ok {
+			return strings.Join(words, " ")
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+var readOnlyVerbRe = regexp.MustCompile(`^(?:describe|get|list|wait|show|head|lookup)(?:-|$)|^update-kubeconfig$|^get-credentials$|^get-caller-identity$`)
+
+// isReadOnly reports whether a command only reads, so it has no place in
+// a module: describe, get, list and wait calls, and kubeconfig updates
+func isReadOnly(args []string) bool {
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "-") {
+			break
+		}
+		if readOnlyVerbRe.MatchString(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// resource adds a resource block labelled after name
+func (e *emitter) resource(kind, name string) *block {
+	b := newBlock("resource", kind, e.label(kind, name))
+	e.blocks = append(e.blocks, b)
+	e.providers[strings.SplitN(kind, "_", 2)[0]] = true
+	return b
+}
+
+// data adds a data source block
+func (e *emitter) data(kind, name string) *block {
+	b := newBlock("data", kind, e.label("data."+kind, name))
+	e.blocks = append(e.blocks, b)
+	e.providers[strings.SplitN(kind, "_", 2)[0]] = true
+	return b
+}
+
+// module adds a registry module block
+func (e *emitter) module(name, source, version string) *block {
+	b := newBlock("module", e.label("module", name))
+	b.set("source", source).set("version", version)
+	e.blocks = append(e.blocks, b)
+	return b
+}
+
+var labelRe = regexp.MustCompile(`[^a-z0-9_]+`)
+
+func (e *emitter) label(kind, name string) string {
+	label := strings.Trim(labelRe.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	switch {
+	case label == "":
+		label = "this"
+	case label[0] >= '0' && label[0] <= '9':
+		label = "r_" + label
+	}
+	unique := label
+	for i := 2; e.labels[kind+"."+unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", label, i)
+	}
+	e.labels[kind+"."+unique] = true
+	return unique
+}
+
+// ref is the expression for the attribute a produces path reads, such as
+// $.Vpc.VpcId for the VPC's id or $.QueueUrl for the queue's url
+func (b *block) ref(path string) string {
+	address := strings.Join(b.labels, ".")
+	if b.kind == "module" {
+		address = "module." + b.labels[0]
+	}
+	if b.output != "" {
+		return address + "." + b.output
+	}
+	last := strings.ToLower(path[strings.LastIndexAny(path, ".]")+1:])
+	switch {
+	case strings.HasSuffix(last, "arn"):
+		return address + ".arn"
+	case strings.HasSuffix(last, "url"):
+		return address + ".url"
+	case strings.HasSuffix(last, "name"):
+		return address + ".name"
+	case strings.HasSuffix(last, "endpoint"):
+		return address + ".endpoint"
+	}
+	return address + ".id"
+}
+
+// value turns a plan argument into an HCL value, replacing bindings with
+// the resources that produce them or with input variables
+func (e *emitter) value(s string) any {
+	if s == "" {
+		return nil
+	}
+	matches := placeholderRe.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(s) {
+		return expr(e.binding(s))
+	}
+	var sb strings.Builder
+	sb.WriteString(`"`)
+	last := 0
+	for _, m := range matches {
+		sb.WriteString(escape(s[last:m[0]]))
+		sb.WriteString("${" + e.binding(s[m[0]:m[1]]) + "}")
+		last = m[1]
+	}
+	sb.WriteString(escape(s[last:]))
+	sb.WriteString(`"`)
+	return expr(sb.String())
+}
+
+func (e *emitter) values(items []string) []any {
+	out := make([]any, 0, len(items))
+	for _, item := range items {
+		for _, part := range strings.Split(item, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, e.value(part))
+			}
+		}
+	}
+	return out
+}
+
+// binding resolves one <NAME> or ${NAME} placeholder
+func (e *emitter) binding(placeholder string) string {
+	m := placeholderRe.FindStringSubmatch(placeholder)
+	name := m[1] + m[2]
+	if ref, ok := e.refs[name]; ok {
+		return ref
+	}
+	description := fmt.Sprintf("Value of %s in the original plan", name)
+	if lookup, ok := e.lookups[name]; ok {
+		description = fmt.Sprintf("Value of %s, looked up in the original plan with: %s", name, lookup)
+	}
+	return e.variable(strings.ToLower(name), description, "", false)
+}
+
+// variable declares an input variable once and returns its reference
+func (e *emitter) variable(name, description, def string, sensitive bool) string {
+	if _, ok := e.variables[name]; !ok {
+		e.variables[name] = &variable{name: name, description: description, def: def, sensitive: sensitive}
+	}
+	return "var." + name
+}
+
+// secret declares a sensitive variable for a credential in the plan, so
+// the value is never written to the module
+func (e *emitter) secret(name, description string) expr {
+	return expr(e.variable(name, description, "", true))
+}
+
+func (e *emitter) versions() string {
+	required := newBlock("required_providers")
+	for _, p := range sortedKeys(e.providers) {
+		source, version := providerSource(p)
+		required.set(p, object{{"source", source}, {"version", version}})
+	}
+	tf := newBlock("terraform").set("required_version", ">= 1.5.0").add(required)
+	var sb strings.Builder
+	tf.render(&sb, "")
+	return sb.String()
+}
+
+func providerSource(name string) (string, string) {
+	switch name {
+	case "google":
+		return "hashicorp/google", ">= 5.0"
+	case "azurerm":
+		return "hashicorp/azurerm", ">= 3.0"
+	}
+	return "hashicorp/" + name, ">= 5.0"
+}
+
+func (e *emitter) providerBlocks() string {
+	var sb strings.Builder
+	for i, p := range sortedKeys(e.providers) {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		b := newBlock("provider", p)
+		switch p {
+		case "aws":
+			b.set("region", expr(e.variable("region", "AWS region to create the resources in", firstNonEmpty(e.region, e.opts.Region), false)))
+		case "google":
+			b.set("project", expr(e.variable("project", "Google Cloud project to create the resources in", e.project, false)))
+			b.set("region", expr(e.variable("region", "Google Cloud region to create the resources in", firstNonEmpty(e.region, e.opts.Region), false)))
+		}
+		b.render(&sb, "")
+	}
+	return sb.String()
+}
+
+func (e *emitter) variableBlocks() string {
+	if len(e.variables) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for i, name := range sortedKeys(e.variables) {
+		v := e.variables[name]
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		b := newBlock("variable", v.name).set("type", expr("string")).set("description", v.description).set("default", v.def)
+		if v.sensitive {
+			b.set("sensitive", true)
+		}
+		b.render(&sb, "")
+	}
+	return sb.String()
+}
+
+func (e *emitter) main(plan *maker.Plan) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Generated by clanker from the plan: %s\n", oneLine(plan.Summary))
+	if q := oneLine(plan.Question); q != "" {
+		fmt.Fprintf(&sb, "# Request: %s\n", q)
+	}
+	for _, b := range e.blocks {
+		sb.WriteString("\n")
+		b.render(&sb, "")
+	}
+	if len(e.skipped) > 0 {
+		sb.WriteString("\n# These plan commands have no Terraform equivalent and were not rendered:\n")
+		for _, s := range e.skipped {
+			fmt.Fprintf(&sb, "#   %s\n", oneLine(s))
+		}
+	}
+	return sb.String()
+}
+
+func (e *emitter) outputs() string {
+	var sb strings.Builder
+	for i, name := range sortedKeys(e.refs) {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		newBlock("output", strings.ToLower(name)).set("value", expr(e.refs[name])).render(&sb, "")
+	}
+	return sb.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,177 @@
+package emit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+func TestRenderMakerPlan(t *testing.T) {
+	plan := &maker.Plan{
+		Summary:  "Create a VPC with a web server and a database",
+		Question: "create a vpc with a web server and postgres",
+		Commands: []maker.Command{
+			{Args: []string{"aws", "sts", "get-caller-identity"}, Produces: map[string]string{"ACCOUNT_ID": "$.Account"}},
+			{
+				Args: []string{"aws", "ec2", "create-vpc", "--cidr-block", "10.0.0.0/16", "--region", "eu-west-1",
+					"--tag-specifications", "ResourceType=vpc,Tags=[{Key=Name,Value=web-vpc}]"},
+				Produces: map[string]string{"VPC_ID": "$.Vpc.VpcId"},
+			},
+			{Args: []string{"aws", "ec2", "create-subnet", "--vpc-id", "<VPC_ID>", "--cidr-block", "10.0.1.0/24", "--availability-zone", "eu-west-1a"}, Produces: map[string]string{"SUBNET_ID": "$.Subnet.SubnetId"}},
+			{Args: []string{"aws", "ec2", "create-security-group", "--group-name", "web", "--description", "web access", "--vpc-id", "<VPC_ID>"}, Produces: map[string]string{"SG_ID": "$.GroupId"}},
+			{Args: []string{"aws", "ec2", "authorize-security-group-ingress", "--group-id", "<SG_ID>", "--protocol", "tcp", "--port", "443", "--cidr", "0.0.0.0/0"}},
+			{Args: []string{"aws", "ec2", "run-instances", "--image-id", "<AMI_ID>", "--instance-type", "t3.micro", "--subnet-id", "<SUBNET_ID>", "--security-group-ids", "<SG_ID>"}},
+			{Args: []string{"aws", "rds", "create-db-instance", "--db-instance-identifier", "web-db", "--engine", "postgres", "--db-instance-class", "db.t3.micro",
+				"--allocated-storage", "20", "--master-username", "app", "--master-user-password", "hunter2"}},
+			{Args: []string{"aws", "s3", "mb", "s3://logs-${ACCOUNT_ID}"}},
+			{Args: []string{"aws", "ec2", "wait", "instance-running"}},
+			{Args: []string{"aws", "ssm", "send-command", "--document-name", "AWS-RunShellScript"}},
+		},
+	}
+
+	module, err := Render(plan, Options{})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if module.Resources != 7 {
+		t.Errorf("Resources = %d, want 7", module.Resources)
+	}
+	if len(module.Skipped) != 1 || !strings.HasPrefix(module.Skipped[0], "aws ssm send-command") {
+		t.Errorf("Skipped = %v, want the ssm command only", module.Skipped)
+	}
+
+	main := module.Files["main.tf"]
+	for _, want := range []string{
+		`resource "aws_vpc" "web_vpc" {`,
+		`vpc_id            = aws_vpc.web_vpc.id`,
+		`security_group_id = aws_security_group.web.id`,
+		`vpc_security_group_ids = [aws_security_group.web.id]`,
+		`ami                    = var.ami_id`,
+		`password            = var.web_db_password`,
+		`bucket = "logs-${var.account_id}"`,
+		`#   aws ssm send-command --document-name AWS-RunShellScript`,
+	} {
+		if !strings.Contains(main, want) {
+			t.Errorf("main.tf missing %q:\n%s", want, main)
+		}
+	}
+	if strings.Contains(main, "hunter2") {
+		t.Errorf("main.tf contains the plan's password:\n%s", main)
+	}
+
+	variables := module.Files["variables.tf"]
+	for _, want := range []string{
+		`variable "account_id" {`,
+		`looked up in the original plan with: aws sts get-caller-identity`,
+		`default     = "eu-west-1"`,
+		`sensitive   = true`,
+	} {
+		if !strings.Contains(variables, want) {
+			t.Errorf("variables.tf missing %q:\n%s", want, variables)
+		}
+	}
+	if !strings.Contains(module.Files["providers.tf"], `region = var.region`) {
+		t.Errorf("providers.tf = %s", module.Files["providers.tf"])
+	}
+	if !strings.Contains(module.Files["outputs.tf"], `output "vpc_id" {`) {
+		t.Errorf("outputs.tf = %s", module.Files["outputs.tf"])
+	}
+}
+
+func TestRenderEKSClusterPlan(t *testing.T) {
+	plan := &maker.Plan{
+		Summary: "Create EKS cluster 'demo' with 3 worker nodes",
+		Commands: []maker.Command{
+			{Args: []string{"eksctl", "create", "cluster", "--name", "demo", "--version", "1.30", "--nodes", "3", "--node-type", "t3.large"}, Produces: map[string]string{"CLUSTER_ENDPOINT": "endpoint="}},
+			{Args: []string{"aws", "eks", "describe-cluster", "--name", "demo", "--query", "cluster.status"}},
+			{Args: []string{"kubectl", "get", "nodes"}},
+			{Args: []string{"aws", "eks", "update-kubeconfig", "--name", "demo"}},
+		},
+	}
+	module, err := Render(plan, Options{Region: "us-west-2"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if len(module.Skipped) != 0 {
+		t.Errorf("Skipped = %v, want none", module.Skipped)
+	}
+	main := module.Files["main.tf"]
+	for _, want := range []string{
+		`data "aws_availability_zones" "available" {`,
+		`module "demo_vpc" {`,
+		`source  = "terraform-aws-modules/eks/aws"`,
+		`subnet_ids                               = module.demo_vpc.private_subnets`,
+		`instance_types = ["t3.large"]`,
+		`"kubernetes.io/role/elb" = "1"`,
+	} {
+		if !strings.Contains(main, want) {
+			t.Errorf("main.tf missing %q:\n%s", want, main)
+		}
+	}
+	if !strings.Contains(module.Files["outputs.tf"], "value = module.demo.cluster_endpoint") {
+		t.Errorf("outputs.tf = %s", module.Files["outputs.tf"])
+	}
+	if !strings.Contains(module.Files["variables.tf"], `default     = "us-west-2"`) {
+		t.Errorf("variables.tf = %s", module.Files["variables.tf"])
+	}
+}
+
+func TestRenderGKEClusterPlan(t *testing.T) {
+	plan := &maker.Plan{
+		Summary: "Create GKE cluster 'demo' with 2 worker nodes",
+		Commands: []maker.Command{
+			{Args: []string{"gcloud", "container", "clusters", "create", "demo", "--project", "acme", "--region", "us-central1",
+				"--num-nodes", "2", "--machine-type", "e2-standard-4", "--preemptible", "--release-channel", "regular"}},
+			{Args: []string{"gcloud", "container", "clusters", "get-credentials", "demo", "--project", "acme", "--region", "us-central1"}},
+		},
+	}
+	module, err := Render(plan, Options{})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	main := module.Files["main.tf"]
+	for _, want := range []string{
+		`resource "google_container_cluster" "demo" {`,
+		`cluster    = google_container_cluster.demo.name`,
+		`preemptible  = true`,
+		`channel = "REGULAR"`,
+	} {
+		if !strings.Contains(main, want) {
+			t.Errorf("main.tf missing %q:\n%s", want, main)
+		}
+	}
+	if !strings.Contains(module.Files["variables.tf"], `default     = "acme"`) {
+		t.Errorf("variables.tf = %s", module.Files["variables.tf"])
+	}
+}
+
+func TestRenderNothingToRender(t *testing.T) {
+	plan := &maker.Plan{Commands: []maker.Command{{Args: []string{"kubectl", "apply", "-f", "app.yaml"}}}}
+	if _, err := Render(plan, Options{}); err == nil {
+		t.Fatal("Render() error = nil, want an error for a plan with no Terraform form")
+	}
+}
+
+func TestModuleWriteRefusesToOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	module := &Module{Files: map[string]string{"main.tf": "# one\n"}}
+	if _, err := module.Write(dir); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := module.Write(dir); err == nil {
+		t.Fatal("second Write() error = nil, want a refusal to overwrite")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "main.tf"))
+	if err != nil || string(data) != "# one\n" {
+		t.Fatalf("main.tf = %q, %v", data, err)
+	}
+}
+
+func TestQuoteEscapesTemplates(t *testing.T) {
+	if got := quote(`a "${b}" %{c}`); got != `"a \"$${b}\" %%{c}"` {
+		t.Errorf("quote() = %s", got)
+	}
+}
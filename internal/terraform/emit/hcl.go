@@ -0,0 +1,181 @@
+package emit
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// expr is an HCL expression written as is, such as a reference
+type expr string
+
+// object is an HCL object written one attribute per line, in order
+type object []attribute
+
+type attribute struct {
+	name  string
+	value any
+}
+
+// block is a Terraform block such as resource "aws_vpc" "main" { ... }
+type block struct {
+	kind   string
+	labels []string
+	attrs  []attribute
+	blocks []*block
+	// output is the attribute bindings produced by this block read, when
+	// it cannot be inferred from the produces path, as for modules
+	output string
+}
+
+func newBlock(kind string, labels ...string) *block {
+	return &block{kind: kind, labels: labels}
+}
+
+// set adds an attribute, skipping empty strings and nil values so handlers
+// can pass optional flags straight through
+func (b *block) set(name string, value any) *block {
+	switch v := value.(type) {
+	case nil:
+		return b
+	case string:
+		if v == "" {
+			return b
+		}
+	case expr:
+		if v == "" {
+			return b
+		}
+	case []any:
+		if len(v) == 0 {
+			return b
+		}
+	case object:
+		if len(v) == 0 {
+			return b
+		}
+	}
+	for i := range b.attrs {
+		if b.attrs[i].name == name {
+			b.attrs[i].value = value
+			return b
+		}
+	}
+	b.attrs = append(b.attrs, attribute{name, value})
+	return b
+}
+
+func (b *block) add(child *block) *block {
+	b.blocks = append(b.blocks, child)
+	return b
+}
+
+func (b *block) get(name string) any {
+	for _, a := range b.attrs {
+		if a.name == name {
+			return a.value
+		}
+	}
+	return nil
+}
+
+// render writes the block the way terraform fmt would: two-space indents
+// and the equals signs of consecutive one-line attributes aligned
+func (b *block) render(sb *strings.Builder, indent string) {
+	sb.WriteString(indent + b.kind)
+	for _, label := range b.labels {
+		sb.WriteString(" " + strconv.Quote(label))
+	}
+	if len(b.attrs) == 0 && len(b.blocks) == 0 {
+		sb.WriteString(" {}\n")
+		return
+	}
+	sb.WriteString(" {\n")
+	attrs := b.attrs
+	if b.kind == "module" && len(attrs) > 2 {
+		// source and version stand apart from the module's inputs
+		writeAttributes(sb, attrs[:2], indent+"  ")
+		sb.WriteString("\n")
+		attrs = attrs[2:]
+	}
+	writeAttributes(sb, attrs, indent+"  ")
+	for i, child := range b.blocks {
+		if i > 0 || len(b.attrs) > 0 {
+			sb.WriteString("\n")
+		}
+		child.render(sb, indent+"  ")
+	}
+	sb.WriteString(indent + "}\n")
+}
+
+func writeAttributes(sb *strings.Builder, attrs []attribute, indent string) {
+	names, rendered := make([]string, len(attrs)), make([]string, len(attrs))
+	for i, a := range attrs {
+		names[i], rendered[i] = attributeName(a.name), renderValue(a.value, indent)
+	}
+	for start := 0; start < len(attrs); {
+		// Align a run of one-line attributes; a multi-line value ends the run
+		end, width := start, 0
+		for end < len(attrs) {
+			if len(names[end]) > width {
+				width = len(names[end])
+			}
+			end++
+			if strings.Contains(rendered[end-1], "\n") {
+				break
+			}
+		}
+		for i := start; i < end; i++ {
+			fmt.Fprintf(sb, "%s%-*s = %s\n", indent, width, names[i], rendered[i])
+		}
+		start = end
+	}
+}
+
+var identifierRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// attributeName quotes object keys that are not identifiers, such as the
+// tag key kubernetes.io/role/elb
+func attributeName(name string) string {
+	if identifierRe.MatchString(name) {
+		return name
+	}
+	return quote(name)
+}
+
+func renderValue(value any, indent string) string {
+	switch v := value.(type) {
+	case expr:
+		return string(v)
+	case string:
+		return quote(v)
+	case int:
+		return strconv.Itoa(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = renderValue(item, indent)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case object:
+		var sb strings.Builder
+		sb.WriteString("{\n")
+		writeAttributes(&sb, v, indent+"  ")
+		sb.WriteString(indent + "}")
+		return sb.String()
+	}
+	return quote(fmt.Sprint(value))
+}
+
+// quote writes s as an HCL string, escaping template sequences so values
+// from the plan are never interpolated
+func quote(s string) string {
+	return `"` + escape(s) + `"`
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "${", "$${", "%{", "%%{").Replace(s)
+}
@@ -0,0 +1,56 @@
+package terraform
+
+import (
+	"regexp"
+	"strings"
+)
+
+// StateOp is what a state question asks for
+type StateOp int
+
+const (
+	// InspectState lists state, or shows one address in it
+	InspectState StateOp = iota
+	// DetectDrift runs a detailed-exitcode plan and looks for unmanaged resources
+	DetectDrift
+)
+
+// StateRequest is a state or drift question parsed into its parts
+type StateRequest struct {
+	Op        StateOp
+	Workspace string
+	Address   string
+}
+
+var (
+	driftRe        = regexp.MustCompile(`(?i)\bdrift(?:ed|ing)?\b|\b(?:managed|created) outside (?:of )?(?:terraform|tf|tofu|opentofu)\b|\bnot (?:managed by|in) (?:terraform|tf|tofu)(?: state)?\b|\bunmanaged resources?\b|\bout of sync with (?:terraform|tf|tofu|state)\b`)
+	stateRe        = regexp.MustCompile(`(?i)\b(?:terraform|tf|tofu|opentofu)\s+state\b|\btfstate\b|\bstate (?:list|show)\b|\bin (?:the )?state\b`)
+	otherDriftRe   = regexp.MustCompile(`(?i)\b(?:argo(?:cd)?|flux|gitops|helm|kubernetes|k8s|cloudformation|pulumi|cdk|crossplane)\b`)
+	workspaceRe    = regexp.MustCompile(`(?i)\bworkspace\s+["']?([a-z0-9][\w.-]*)|\b(?:for|in|of)\s+(?:the\s+)?["']?([a-z0-9][\w.-]*)["']?\s+workspace\b`)
+	stateAddressRe = regexp.MustCompile(`\b((?:module\.[\w-]+(?:\[[^\]\s]+\])?\.)*(?:data\.)?[a-z][a-z0-9]*_[a-z0-9_]+\.[\w-]+(?:\[[^\]\s]+\])?)`)
+)
+
+// IsStateQuestion reports whether question asks what Terraform state holds
+// or whether infrastructure has drifted from it
+func IsStateQuestion(question string) bool {
+	if driftRe.MatchString(question) {
+		return !otherDriftRe.MatchString(question)
+	}
+	return stateRe.MatchString(question)
+}
+
+// ParseStateRequest reads the operation, workspace and resource address
+// from question
+func ParseStateRequest(question string) StateRequest {
+	req := StateRequest{Op: InspectState}
+	if driftRe.MatchString(question) {
+		req.Op = DetectDrift
+	}
+	if m := workspaceRe.FindStringSubmatch(question); m != nil {
+		req.Workspace = strings.ToLower(m[1] + m[2])
+	}
+	if req.Op == InspectState {
+		req.Address = stateAddressRe.FindString(question)
+	}
+	return req
+}
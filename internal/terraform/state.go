@@ -0,0 +1,155 @@
+package terraform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const stateCommandTimeout = 30 * time.Second
+
+// ManagedResource is one managed resource in state, with the identifiers
+// used to match it against live cloud inventory
+type ManagedResource struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`
+	ARN     string `json:"arn,omitempty"`
+}
+
+// StateInventory is what `state list` reports for a workspace, plus the
+// `state show` output of one address when one was asked for
+type StateInventory struct {
+	Workspace string   `json:"workspace"`
+	Path      string   `json:"path"`
+	Tool      string   `json:"tool"`
+	Addresses []string `json:"addresses"`
+	Address   string   `json:"address,omitempty"`
+	Detail    string   `json:"detail,omitempty"`
+}
+
+// Workspace returns the configured workspace name the client runs in
+func (c *Client) Workspace() string {
+	return c.workspace
+}
+
+// StateList returns every address in the workspace's state
+func (c *Client) StateList(ctx context.Context) ([]string, error) {
+	output, err := runTerraformCommand(ctx, c.path, c.binary, stateCommandTimeout, "state", "list")
+	if err != nil {
+		return nil, err
+	}
+	return nonEmptyLines(output), nil
+}
+
+// StateShow returns the attributes state holds for address
+func (c *Client) StateShow(ctx context.Context, address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if address == "" || strings.HasPrefix(address, "-") {
+		return "", fmt.Errorf("invalid state address %q", address)
+	}
+	return runTerraformCommand(ctx, c.path, c.binary, stateCommandTimeout, "state", "show", "-no-color", address)
+}
+
+// Inventory lists the workspace's state and, when address is set, shows it
+func (c *Client) Inventory(ctx context.Context, address string) (*StateInventory, error) {
+	addresses, err := c.StateList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inv := &StateInventory{Workspace: c.workspace, Path: c.path, Tool: displayToolName(c.binary), Addresses: addresses, Address: address}
+	if address != "" {
+		detail, err := c.StateShow(ctx, address)
+		if err != nil {
+			return nil, err
+		}
+		inv.Detail = detail
+	}
+	return inv, nil
+}
+
+// ManagedResources reads the IDs and ARNs of every managed resource in
+// state. One `show -json` is used instead of a `state show` per address.
+func (c *Client) ManagedResources(ctx context.Context) ([]ManagedResource, error) {
+	output, err := runTerraformCommand(ctx, c.path, c.binary, stateCommandTimeout, "show", "-json")
+	if err != nil {
+		return nil, err
+	}
+	return parseManagedResources([]byte(output))
+}
+
+type stateModule struct {
+	Resources []struct {
+		Address string                 `json:"address"`
+		Mode    string                 `json:"mode"`
+		Type    string                 `json:"type"`
+		Values  map[string]interface{} `json:"values"`
+	} `json:"resources"`
+	ChildModules []stateModule `json:"child_modules"`
+}
+
+func parseManagedResources(data []byte) ([]ManagedResource, error) {
+	var state struct {
+		Values *struct {
+			RootModule stateModule `json:"root_module"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse terraform state: %w", err)
+	}
+	if state.Values == nil {
+		return nil, nil
+	}
+	var resources []ManagedResource
+	var walk func(m stateModule)
+	walk = func(m stateModule) {
+		for _, r := range m.Resources {
+			if r.Mode != "managed" {
+				continue
+			}
+			id, _ := r.Values["id"].(string)
+			arn, _ := r.Values["arn"].(string)
+			resources = append(resources, ManagedResource{Address: r.Address, Type: r.Type, ID: id, ARN: arn})
+		}
+		for _, child := range m.ChildModules {
+			walk(child)
+		}
+	}
+	walk(state.Values.RootModule)
+	return resources, nil
+}
+
+// Format renders the state grouped by resource type, followed by the
+// shown address's attributes
+func (inv *StateInventory) Format() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s state for workspace %s (%s)\n", inv.Tool, fallbackViewText(inv.Workspace, "local"), inv.Path)
+	if len(inv.Addresses) == 0 {
+		sb.WriteString("No resources in state.\n")
+	} else {
+		byType := make(map[string][]string)
+		for _, address := range inv.Addresses {
+			resourceType := fallbackViewText(resourceTypeFromAddress(address), "other")
+			byType[resourceType] = append(byType[resourceType], address)
+		}
+		types := make([]string, 0, len(byType))
+		for resourceType := range byType {
+			types = append(types, resourceType)
+		}
+		sort.Strings(types)
+		fmt.Fprintf(&sb, "Resources: %d across %d type(s)\n", len(inv.Addresses), len(types))
+		for _, resourceType := range types {
+			fmt.Fprintf(&sb, "\n%s (%d):\n", resourceType, len(byType[resourceType]))
+			for _, address := range byType[resourceType] {
+				fmt.Fprintf(&sb, "  - %s\n", address)
+			}
+		}
+	}
+	if inv.Address != "" {
+		fmt.Fprintf(&sb, "\n%s:\n%s\n", inv.Address, strings.TrimSpace(inv.Detail))
+	}
+	return sb.String()
+}
@@ -0,0 +1,123 @@
+package terraform
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStateRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     StateRequest
+	}{
+		{"what's in the terraform state for workspace prod", StateRequest{Op: InspectState, Workspace: "prod"}},
+		{"terraform state show module.vpc.aws_subnet.private[\"a\"] in the staging workspace", StateRequest{Op: InspectState, Workspace: "staging", Address: `module.vpc.aws_subnet.private["a"]`}},
+		{"is there drift", StateRequest{Op: DetectDrift}},
+		{"has aws_instance.web drifted in workspace prod", StateRequest{Op: DetectDrift, Workspace: "prod"}},
+	}
+	for _, tt := range tests {
+		if got := ParseStateRequest(tt.question); got != tt.want {
+			t.Errorf("ParseStateRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestParseManagedResources(t *testing.T) {
+	resources, err := parseManagedResources([]byte(`{"values": {"root_module": {
+		"resources": [
+			{"address": "aws_s3_bucket.logs", "mode": "managed", "type": "aws_s3_bucket", "values": {"id": "acme-logs", "arn": "arn:aws:s3:::acme-logs"}},
+			{"address": "data.aws_caller_identity.me", "mode": "data", "type": "aws_caller_identity", "values": {"id": "123456789012"}}],
+		"child_modules": [{"resources": [
+			{"address": "module.web.aws_instance.web", "mode": "managed", "type": "aws_instance", "values": {"id": "i-0abc", "arn": "arn:aws:ec2:us-east-1:123456789012:instance/i-0abc"}}]}]}}}`))
+	if err != nil {
+		t.Fatalf("parseManagedResources: %v", err)
+	}
+	want := []ManagedResource{
+		{Address: "aws_s3_bucket.logs", Type: "aws_s3_bucket", ID: "acme-logs", ARN: "arn:aws:s3:::acme-logs"},
+		{Address: "module.web.aws_instance.web", Type: "aws_instance", ID: "i-0abc", ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-0abc"},
+	}
+	if !reflect.DeepEqual(resources, want) {
+		t.Errorf("resources = %+v", resources)
+	}
+
+	if resources, err := parseManagedResources([]byte(`{"format_version": "1.0"}`)); err != nil || len(resources) != 0 {
+		t.Errorf("empty state = %v, %v", resources, err)
+	}
+}
+
+func TestPlanChanges(t *testing.T) {
+	output := `Note: Objects have changed outside of Terraform
+
+  # aws_security_group.web has changed
+  # module.db.aws_db_instance.main has been deleted
+
+Terraform will perform the following actions:
+
+  # aws_security_group.web will be updated in-place
+  # aws_instance.web must be replaced
+Plan: 1 to add, 1 to change, 1 to destroy.`
+	want := []PlanChange{
+		{"aws_security_group.web", "has changed"},
+		{"module.db.aws_db_instance.main", "has been deleted"},
+		{"aws_security_group.web", "will be updated in-place"},
+		{"aws_instance.web", "must be replaced"},
+	}
+	if got := planChanges(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("planChanges = %+v", got)
+	}
+}
+
+func TestUnmanaged(t *testing.T) {
+	live, err := ParseTaggedResources([]byte(`{"ResourceTagMappingList": [
+		{"ResourceARN": "arn:aws:s3:::acme-logs"},
+		{"ResourceARN": "arn:aws:s3:::acme-scratch"},
+		{"ResourceARN": "arn:aws:ec2:us-east-1:123456789012:instance/i-0abc"},
+		{"ResourceARN": "arn:aws:ec2:us-east-1:123456789012:security-group/sg-0123"},
+		{"ResourceARN": "arn:aws:logs:us-east-1:123456789012:log-group:/aws/lambda/checkout"},
+		{"ResourceARN": "not-an-arn"}]}`))
+	if err != nil {
+		t.Fatalf("ParseTaggedResources: %v", err)
+	}
+	if len(live) != 5 || live[3] != (LiveResource{ARN: "arn:aws:ec2:us-east-1:123456789012:security-group/sg-0123", Service: "ec2", Type: "security-group", ID: "sg-0123"}) {
+		t.Fatalf("live = %+v", live)
+	}
+
+	managed := []ManagedResource{
+		{Address: "aws_s3_bucket.logs", ARN: "arn:aws:s3:::acme-logs", ID: "acme-logs"},
+		{Address: "aws_instance.web", ID: "i-0abc"},
+		{Address: "aws_cloudwatch_log_group.checkout", ID: "/aws/lambda/checkout", ARN: "arn:aws:logs:us-east-1:123456789012:log-group:/aws/lambda/checkout:*"},
+	}
+	var arns []string
+	for _, r := range Unmanaged(managed, live) {
+		arns = append(arns, r.ARN)
+	}
+	want := []string{"arn:aws:ec2:us-east-1:123456789012:security-group/sg-0123", "arn:aws:s3:::acme-scratch"}
+	if !reflect.DeepEqual(arns, want) {
+		t.Errorf("unmanaged = %v, want %v", arns, want)
+	}
+}
+
+func TestDriftCheckFormat(t *testing.T) {
+	check := &DriftCheck{
+		Workspace:   "prod",
+		Path:        "/infra/prod",
+		Tool:        "Terraform",
+		Plan:        &DriftReport{Checked: true, HasChanges: true, ExitCode: 2, Command: "terraform plan -detailed-exitcode", Summary: []string{"Plan: 0 to add, 1 to change, 0 to destroy."}},
+		Changes:     []PlanChange{{"aws_security_group.web", "will be updated in-place"}},
+		Managed:     12,
+		LiveChecked: true,
+		LiveCount:   14,
+		Unmanaged:   []LiveResource{{ARN: "arn:aws:s3:::acme-scratch"}},
+	}
+	out := check.Format()
+	for _, want := range []string{
+		"Drift: yes, the plan is not empty",
+		"  - aws_security_group.web will be updated in-place",
+		"1 of 14 live resource(s) are not in state (12 managed):\n  - arn:aws:s3:::acme-scratch",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("format missing %q:\n%s", want, out)
+		}
+	}
+}
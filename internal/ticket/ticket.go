@@ -0,0 +1,309 @@
+// Package ticket turns a stored diagnostic or audit report into an issue in
+// Linear or a page in Notion. The ticket body is markdown: Linear renders it
+// directly and Notion gets it through notion.MarkdownToBlocks. Every ticket
+// ends with a pointer back to the stored report so whoever picks it up can
+// see the full output.
+package ticket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/aws/posture"
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/bgdnvk/clanker/internal/linear"
+	"github.com/bgdnvk/clanker/internal/notion"
+)
+
+// Trackers
+const (
+	TrackerLinear = "linear"
+	TrackerNotion = "notion"
+)
+
+// Linear priorities; Notion tickets carry the priority in the body only
+const (
+	PriorityUrgent = 1
+	PriorityHigh   = 2
+	PriorityMedium = 3
+	PriorityLow    = 4
+)
+
+// Ticket is an issue ready to file
+type Ticket struct {
+	Title    string
+	Markdown string
+	Priority int
+}
+
+// Ref points a ticket back at the report it was filed from
+type Ref struct {
+	ID   string
+	Path string
+}
+
+// ParseTracker normalises a --create-ticket value
+func ParseTracker(s string) (string, error) {
+	switch t := strings.ToLower(strings.TrimSpace(s)); t {
+	case TrackerLinear, TrackerNotion:
+		return t, nil
+	case "":
+		return "", nil
+	}
+	return "", fmt.Errorf("unsupported ticket tracker %q (use linear or notion)", s)
+}
+
+var (
+	fileTicketRe = regexp.MustCompile(`\b(file|open|create|raise|log|make)\s+(a\s+|an\s+)?(new\s+)?(linear\s+|notion\s+)?(ticket|issue|page)\b`)
+	howToRe      = regexp.MustCompile(`^(how\s+(do|can|should)\s+(i|we)|how\s+to|what\s+is|explain)\b`)
+)
+
+// RequestedTracker reports whether question asks to file a ticket for the
+// last report, and in which tracker. The tracker is empty when the question
+// does not name one.
+func RequestedTracker(question string) (tracker string, ok bool) {
+	q := strings.ToLower(strings.TrimSpace(question))
+	if howToRe.MatchString(q) || !fileTicketRe.MatchString(q) {
+		return "", false
+	}
+	// "create an issue" alone is too close to GitHub and Linear requests
+	// that have nothing to do with a report
+	if !strings.Contains(q, "ticket") && !refersToReport(q) {
+		return "", false
+	}
+	switch {
+	case strings.Contains(q, "linear"):
+		tracker = TrackerLinear
+	case strings.Contains(q, "notion"):
+		tracker = TrackerNotion
+	}
+	return tracker, true
+}
+
+func refersToReport(q string) bool {
+	for _, w := range []string{"for this", "for that", "for it", "report", "diagnosis", "finding", "audit"} {
+		if strings.Contains(q, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromDiagnosticReport builds a ticket from an SRE diagnostic report
+func FromDiagnosticReport(r *sre.DiagnosticReport, ref Ref) Ticket {
+	subject := r.Scope
+	if r.ResourceName != "" {
+		subject = r.ResourceName
+		if r.ResourceType != "" {
+			subject = r.ResourceType + "/" + r.ResourceName
+		}
+	}
+	if r.Namespace != "" {
+		subject += " in " + r.Namespace
+	}
+
+	critical, warning := 0, 0
+	for _, issue := range r.Issues {
+		switch issue.Severity {
+		case sre.SeverityCritical:
+			critical++
+		case sre.SeverityWarning:
+			warning++
+		}
+	}
+	t := Ticket{Title: "Diagnosis: " + subject, Priority: PriorityLow}
+	switch {
+	case critical > 0:
+		t.Title = fmt.Sprintf("%s: %d critical issue%s", subject, critical, plural(critical))
+		t.Priority = PriorityHigh
+	case warning > 0:
+		t.Title = fmt.Sprintf("%s: %d warning%s", subject, warning, plural(warning))
+		t.Priority = PriorityMedium
+	}
+
+	var b strings.Builder
+	if r.Summary != "" {
+		fmt.Fprintf(&b, "%s\n\n", r.Summary)
+	}
+	if len(r.RootCauses) > 0 {
+		b.WriteString("## Root cause\n\n")
+		for _, rc := range r.RootCauses {
+			where := rc.Pod
+			if rc.Container != "" {
+				where += "/" + rc.Container
+			}
+			fmt.Fprintf(&b, "- **%s** (%s confidence): %s\n", where, rc.Confidence, rc.Summary)
+		}
+		b.WriteString("\n")
+	}
+	if len(r.Issues) > 0 {
+		b.WriteString("## Issues\n\n")
+		for _, issue := range r.Issues {
+			fmt.Fprintf(&b, "- **%s** %s/%s: %s\n", strings.ToUpper(string(issue.Severity)), issue.ResourceType, issue.ResourceName, issue.Message)
+		}
+		b.WriteString("\n")
+	}
+	if len(r.TrackedErrors) > 0 {
+		b.WriteString("## Related errors\n\n")
+		for _, e := range r.TrackedErrors {
+			line := fmt.Sprintf("%s %s: %s", e.Source, e.ID, e.Title)
+			if e.Link != "" {
+				line = fmt.Sprintf("[%s %s](%s): %s", e.Source, e.ID, e.Link, e.Title)
+			}
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+		b.WriteString("\n")
+	}
+	if len(r.Remediation) > 0 {
+		b.WriteString("## Remediation\n\n")
+		for _, step := range r.Remediation {
+			fmt.Fprintf(&b, "%d. %s\n", step.Order, step.Description)
+			if step.Command != "" {
+				fmt.Fprintf(&b, "   `%s`\n", strings.TrimSpace(step.Command+" "+strings.Join(step.Args, " ")))
+			}
+		}
+		b.WriteString("\n")
+	}
+	writeRef(&b, ref)
+	t.Markdown = b.String()
+	return t
+}
+
+// FromAuditReport builds a ticket from an AWS posture report. Findings are
+// listed most severe first, as the audit reports them.
+func FromAuditReport(r *posture.Report, ref Ref) Ticket {
+	t := Ticket{Title: "AWS security audit: no findings", Priority: PriorityLow}
+	if len(r.Findings) > 0 {
+		worst := r.Findings[0].Severity
+		for _, f := range r.Findings {
+			if f.Severity < worst {
+				worst = f.Severity
+			}
+		}
+		t.Title = fmt.Sprintf("AWS security audit: %d finding%s (worst %s)", len(r.Findings), plural(len(r.Findings)), worst)
+		switch worst {
+		case posture.Critical:
+			t.Priority = PriorityUrgent
+		case posture.High:
+			t.Priority = PriorityHigh
+		case posture.Medium:
+			t.Priority = PriorityMedium
+		}
+	}
+
+	var b strings.Builder
+	if len(r.Findings) > 0 {
+		b.WriteString("## Findings\n\n")
+		for _, f := range r.Findings {
+			fmt.Fprintf(&b, "- **%s** %s: %s (`%s`)\n", f.Severity, f.Check, f.Title, f.Resource)
+			if f.Remediation != "" {
+				fmt.Fprintf(&b, "  - Fix: %s\n", f.Remediation)
+			}
+			if len(f.Fix) > 0 {
+				fmt.Fprintf(&b, "  - `%s`\n", strings.Join(f.Fix, " "))
+			}
+		}
+		b.WriteString("\n")
+	}
+	if len(r.Skipped) > 0 {
+		b.WriteString("## Checks that did not run\n\n")
+		for _, s := range r.Skipped {
+			fmt.Fprintf(&b, "- %s\n", s)
+		}
+		b.WriteString("\n")
+	}
+	writeRef(&b, ref)
+	t.Markdown = b.String()
+	return t
+}
+
+func writeRef(b *strings.Builder, ref Ref) {
+	if ref.ID == "" {
+		return
+	}
+	fmt.Fprintf(b, "---\n\nFiled by clanker from report `%s`. Run `clanker report show %s` to see the full report", ref.ID, ref.ID)
+	if ref.Path != "" {
+		fmt.Fprintf(b, " (stored at `%s`)", ref.Path)
+	}
+	b.WriteString(".\n")
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// LinearAPI is the part of linear.Client used to file issues
+type LinearAPI interface {
+	GetTeam(ctx context.Context, idOrKey string) (*linear.Team, []linear.WorkflowState, error)
+	CreateIssue(ctx context.Context, input linear.CreateIssueInput) (*linear.Issue, error)
+}
+
+// FileLinear creates t as an issue in team, given by key or ID, and returns
+// its identifier and URL
+func FileLinear(ctx context.Context, api LinearAPI, team string, t Ticket) (id, url string, err error) {
+	if strings.TrimSpace(team) == "" {
+		return "", "", fmt.Errorf("no linear team to file into (set linear.default_team in ~/.clanker.yaml or export LINEAR_TEAM)")
+	}
+	resolved, _, err := api.GetTeam(ctx, team)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to look up linear team %s: %w", team, err)
+	}
+	issue, err := api.CreateIssue(ctx, linear.CreateIssueInput{
+		Title:       t.Title,
+		Description: t.Markdown,
+		TeamID:      resolved.ID,
+		Priority:    t.Priority,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create linear issue: %w", err)
+	}
+	return issue.Identifier, issue.URL, nil
+}
+
+// NotionAPI is the part of notion.Client used to file pages
+type NotionAPI interface {
+	GetDatabase(ctx context.Context, id string) (*notion.Database, error)
+	CreatePage(ctx context.Context, parentType, parentID string, properties map[string]any, children []map[string]any) (*notion.Page, error)
+}
+
+// FileNotion creates t as a page in databaseID and returns its ID and URL.
+// The title goes into whichever property the database uses as its title,
+// since Notion lets every workspace name that column.
+func FileNotion(ctx context.Context, api NotionAPI, databaseID string, t Ticket) (id, url string, err error) {
+	if strings.TrimSpace(databaseID) == "" {
+		return "", "", fmt.Errorf("no notion database to file into (set notion.default_database_id in ~/.clanker.yaml or export NOTION_DATABASE_ID)")
+	}
+	db, err := api.GetDatabase(ctx, databaseID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to look up notion database %s: %w", databaseID, err)
+	}
+	titleProp := ""
+	for name, raw := range db.Properties {
+		var prop struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(raw, &prop) == nil && prop.Type == "title" {
+			titleProp = name
+			break
+		}
+	}
+	if titleProp == "" {
+		return "", "", fmt.Errorf("notion database %s has no title property", databaseID)
+	}
+	props := notion.TitleProperty(t.Title)
+	if titleProp != "title" {
+		props[titleProp] = props["title"]
+		delete(props, "title")
+	}
+	page, err := api.CreatePage(ctx, notion.ParentTypeDatabase, db.ID, props, notion.MarkdownToBlocks(t.Markdown))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create notion page: %w", err)
+	}
+	return page.ID, page.URL, nil
+}
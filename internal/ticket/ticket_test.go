@@ -0,0 +1,145 @@
+package ticket
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bgdnvk/clanker/internal/aws/posture"
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+	"github.com/bgdnvk/clanker/internal/linear"
+	"github.com/bgdnvk/clanker/internal/notion"
+)
+
+func TestRequestedTracker(t *testing.T) {
+	tests := []struct {
+		question string
+		tracker  string
+		ok       bool
+	}{
+		{"file a ticket for this", "", true},
+		{"open a linear ticket for this diagnosis", TrackerLinear, true},
+		{"create a notion page for the audit report", TrackerNotion, true},
+		{"file an issue for that", "", true},
+		{"how do I file a ticket in linear", "", false},
+		{"create an issue in linear for the login bug", "", false},
+		{"why is deployment api failing", "", false},
+	}
+	for _, tt := range tests {
+		tracker, ok := RequestedTracker(tt.question)
+		if tracker != tt.tracker || ok != tt.ok {
+			t.Errorf("RequestedTracker(%q) = %q, %v; want %q, %v", tt.question, tracker, ok, tt.tracker, tt.ok)
+		}
+	}
+}
+
+func TestFromDiagnosticReport(t *testing.T) {
+	r := &sre.DiagnosticReport{
+		Scope:        "resource",
+		ResourceType: "deployment",
+		ResourceName: "api",
+		Namespace:    "prod",
+		Summary:      "deployment api has 1 issues",
+		Issues: []sre.Issue{{Severity: sre.SeverityCritical, ResourceType: sre.ResourceType("pod"), ResourceName: "api-7f9c",
+			Message: "container api is in CrashLoopBackOff"}},
+		RootCauses:    []sre.RootCause{{Pod: "api-7f9c", Container: "api", Confidence: "high", Summary: "OOMKilled at 512Mi"}},
+		Remediation:   []sre.RemediationStep{{Order: 1, Description: "Raise the memory limit", Command: "kubectl", Args: []string{"set", "resources", "deployment/api", "--limits=memory=1Gi"}}},
+		TrackedErrors: []sre.TrackedError{{Source: "sentry", ID: "BACKEND-42", Title: "MemoryError", Link: "https://sentry.io/issues/4012345/"}},
+	}
+	tk := FromDiagnosticReport(r, Ref{ID: "20261015-120000-ab12", Path: "/home/me/.clanker/reports/20261015-120000-ab12.json"})
+	if tk.Title != "deployment/api in prod: 1 critical issue" || tk.Priority != PriorityHigh {
+		t.Errorf("title, priority = %q, %d", tk.Title, tk.Priority)
+	}
+	for _, s := range []string{
+		"## Root cause", "**api-7f9c/api** (high confidence): OOMKilled at 512Mi",
+		"**CRITICAL** pod/api-7f9c: container api is in CrashLoopBackOff",
+		"[sentry BACKEND-42](https://sentry.io/issues/4012345/)",
+		"`kubectl set resources deployment/api --limits=memory=1Gi`",
+		"clanker report show 20261015-120000-ab12",
+	} {
+		if !strings.Contains(tk.Markdown, s) {
+			t.Errorf("markdown missing %q:\n%s", s, tk.Markdown)
+		}
+	}
+}
+
+func TestFromAuditReport(t *testing.T) {
+	r := &posture.Report{
+		Findings: []posture.Finding{
+			{Severity: posture.High, Check: "Security groups", Resource: "sg-123", Title: "SSH open to the world", Remediation: "Restrict port 22",
+				Fix: []string{"aws", "ec2", "revoke-security-group-ingress", "--group-id", "sg-123"}},
+			{Severity: posture.Medium, Check: "IAM", Resource: "user/ci", Title: "Access key older than 90 days"},
+		},
+		Skipped: []string{"CloudTrail: access denied"},
+	}
+	tk := FromAuditReport(r, Ref{ID: "20261015-120000-cd34"})
+	if tk.Title != "AWS security audit: 2 findings (worst HIGH)" || tk.Priority != PriorityHigh {
+		t.Errorf("title, priority = %q, %d", tk.Title, tk.Priority)
+	}
+	for _, s := range []string{"**HIGH** Security groups: SSH open to the world (`sg-123`)", "`aws ec2 revoke-security-group-ingress --group-id sg-123`", "CloudTrail: access denied", "clanker report show 20261015-120000-cd34"} {
+		if !strings.Contains(tk.Markdown, s) {
+			t.Errorf("markdown missing %q:\n%s", s, tk.Markdown)
+		}
+	}
+}
+
+type fakeLinear struct {
+	created linear.CreateIssueInput
+}
+
+func (f *fakeLinear) GetTeam(ctx context.Context, idOrKey string) (*linear.Team, []linear.WorkflowState, error) {
+	return &linear.Team{ID: "team-uuid", Key: idOrKey}, nil, nil
+}
+
+func (f *fakeLinear) CreateIssue(ctx context.Context, input linear.CreateIssueInput) (*linear.Issue, error) {
+	f.created = input
+	return &linear.Issue{Identifier: "OPS-12", URL: "https://linear.app/acme/issue/OPS-12"}, nil
+}
+
+func TestFileLinear(t *testing.T) {
+	api := &fakeLinear{}
+	id, url, err := FileLinear(context.Background(), api, "OPS", Ticket{Title: "t", Markdown: "body", Priority: PriorityHigh})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "OPS-12" || url != "https://linear.app/acme/issue/OPS-12" {
+		t.Errorf("id, url = %q, %q", id, url)
+	}
+	if api.created.TeamID != "team-uuid" || api.created.Priority != PriorityHigh || api.created.Description != "body" {
+		t.Errorf("input = %+v", api.created)
+	}
+	if _, _, err := FileLinear(context.Background(), api, "", Ticket{}); err == nil {
+		t.Error("expected an error without a team")
+	}
+}
+
+type fakeNotion struct {
+	props map[string]any
+}
+
+func (f *fakeNotion) GetDatabase(ctx context.Context, id string) (*notion.Database, error) {
+	return &notion.Database{ID: id, Properties: map[string]json.RawMessage{
+		"Status": json.RawMessage(`{"type":"select"}`),
+		"Name":   json.RawMessage(`{"type":"title"}`),
+	}}, nil
+}
+
+func (f *fakeNotion) CreatePage(ctx context.Context, parentType, parentID string, properties map[string]any, children []map[string]any) (*notion.Page, error) {
+	f.props = properties
+	return &notion.Page{ID: "page-1", URL: "https://www.notion.so/page-1"}, nil
+}
+
+func TestFileNotion(t *testing.T) {
+	api := &fakeNotion{}
+	id, url, err := FileNotion(context.Background(), api, "db-1", Ticket{Title: "t", Markdown: "body"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "page-1" || url != "https://www.notion.so/page-1" {
+		t.Errorf("id, url = %q, %q", id, url)
+	}
+	if _, ok := api.props["Name"]; !ok || len(api.props) != 1 {
+		t.Errorf("properties = %v, want the title under Name", api.props)
+	}
+}
@@ -374,6 +374,16 @@ func (c *Client) GetRelevantContext(ctx context.Context, question string) (strin
 		}
 	}
 
+	if IsFailedDeployQuestion(question) {
+		failed, err := NewOps(c, time.Now()).FailedDeploymentContext(ctx, opProject(question))
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("Latest Failed Deployment: %v", err))
+		} else {
+			out.WriteString(failed)
+			out.WriteString("\n")
+		}
+	}
+
 	if len(warnings) > 0 {
 		out.WriteString("Vercel Warnings:\n")
 		for i, warn := range warnings {
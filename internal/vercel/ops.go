@@ -0,0 +1,319 @@
+package vercel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// OpsAPI is the part of Client the ops agent calls, so tests can stand in
+// for Vercel.
+type OpsAPI interface {
+	RunAPIWithContext(ctx context.Context, method, endpoint, body string) (string, error)
+}
+
+// buildLogTail is how many of a build's last output lines are shown; the
+// error that failed a build is almost always at the end.
+const buildLogTail = 40
+
+// Ops answers OpRequests. Reads run straight against the REST API; changes
+// are built into plans by BuildOpPlan and only run through `ask --apply`.
+type Ops struct {
+	api OpsAPI
+	now time.Time
+}
+
+// NewOps returns an ops agent over api. now dates deployments in listings.
+func NewOps(api OpsAPI, now time.Time) *Ops {
+	return &Ops{api: api, now: now}
+}
+
+// Read lists projects, deployments or a project's env names, or fetches the
+// build logs of the deployment req picks, rendered as text.
+func (o *Ops) Read(ctx context.Context, req OpRequest) (string, error) {
+	switch req.Op {
+	case OpListProjects:
+		out, err := o.api.RunAPIWithContext(ctx, "GET", "/v9/projects?limit=50", "")
+		if err != nil {
+			return "", err
+		}
+		var resp struct {
+			Projects []Project `json:"projects"`
+		}
+		if err := json.Unmarshal([]byte(out), &resp); err != nil {
+			return "", fmt.Errorf("failed to parse projects: %w", err)
+		}
+		return o.formatProjects(resp.Projects), nil
+	case OpListDeployments:
+		deployments, err := o.deployments(ctx, req.Project, req.Target, false, 20)
+		if err != nil {
+			return "", err
+		}
+		return o.formatDeployments(deployments), nil
+	case OpBuildLogs:
+		d, err := o.LatestDeployment(ctx, req.Project, req.Target, req.Failed)
+		if err != nil {
+			return "", err
+		}
+		return o.buildReport(ctx, d)
+	case OpListEnv:
+		out, err := o.api.RunAPIWithContext(ctx, "GET", "/v10/projects/"+url.PathEscape(req.Project)+"/env", "")
+		if err != nil {
+			return "", err
+		}
+		var resp struct {
+			Envs []EnvVar `json:"envs"`
+		}
+		if err := json.Unmarshal([]byte(out), &resp); err != nil {
+			return "", fmt.Errorf("failed to parse env vars of %s: %w", req.Project, err)
+		}
+		return formatEnv(req.Project, resp.Envs), nil
+	}
+	return "", fmt.Errorf("%s is not a read operation", req.Op)
+}
+
+// LatestDeployment returns the newest deployment, of project when one is
+// named and of target when one is given, and only failed ones when failed
+// is set.
+func (o *Ops) LatestDeployment(ctx context.Context, project, target string, failed bool) (*Deployment, error) {
+	deployments, err := o.deployments(ctx, project, target, failed, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(deployments) == 0 {
+		what := "deployments"
+		if failed {
+			what = "failed deployments"
+		}
+		if project != "" {
+			return nil, fmt.Errorf("no %s found for project %s", what, project)
+		}
+		return nil, fmt.Errorf("no %s found", what)
+	}
+	return &deployments[0], nil
+}
+
+func (o *Ops) deployments(ctx context.Context, project, target string, failed bool, limit int) ([]Deployment, error) {
+	q := url.Values{}
+	q.Set("limit", fmt.Sprint(limit))
+	if project != "" {
+		q.Set("projectId", project)
+	}
+	if target != "" && target != "development" {
+		q.Set("target", target)
+	}
+	if failed {
+		q.Set("state", "ERROR")
+	}
+	out, err := o.api.RunAPIWithContext(ctx, "GET", "/v6/deployments?"+q.Encode(), "")
+	if err != nil {
+		return nil, err
+	}
+	return parseVercelDeployments(out)
+}
+
+// FailedDeploymentContext renders the latest failed deployment of project,
+// or of any project, with its error and the tail of its build output, for
+// the context agent to explain.
+func (o *Ops) FailedDeploymentContext(ctx context.Context, project string) (string, error) {
+	d, err := o.LatestDeployment(ctx, project, "", true)
+	if err != nil {
+		return "", err
+	}
+	report, err := o.buildReport(ctx, d)
+	if err != nil {
+		return "", err
+	}
+	return "Latest Failed Deployment:\n" + report, nil
+}
+
+// buildReport renders a deployment's state, error and build output tail.
+func (o *Ops) buildReport(ctx context.Context, d *Deployment) (string, error) {
+	// The listing leaves out the error fields
+	if out, err := o.api.RunAPIWithContext(ctx, "GET", "/v13/deployments/"+url.PathEscape(d.UID), ""); err == nil {
+		var full Deployment
+		if json.Unmarshal([]byte(out), &full) == nil {
+			withErr := *d
+			withErr.ErrorCode, withErr.ErrorMessage, withErr.ErrorStep = full.ErrorCode, full.ErrorMessage, full.ErrorStep
+			if full.Meta != nil {
+				withErr.Meta = full.Meta
+			}
+			d = &withErr
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Deployment %s of %s (%s, %s)\n", d.UID, d.Name, deploymentState(*d), deploymentTarget(*d))
+	if d.URL != "" {
+		fmt.Fprintf(&sb, "  URL: https://%s\n", d.URL)
+	}
+	if ref, msg := metaString(*d, "githubCommitRef"), metaString(*d, "githubCommitMessage"); ref != "" || msg != "" {
+		fmt.Fprintf(&sb, "  Commit: %s %s\n", ref, firstLine(msg))
+	}
+	if d.Created > 0 {
+		fmt.Fprintf(&sb, "  Created: %s\n", time.UnixMilli(d.Created).UTC().Format(time.RFC3339))
+	}
+	if d.ErrorMessage != "" || d.ErrorCode != "" {
+		fmt.Fprintf(&sb, "  Error: %s", d.ErrorMessage)
+		if d.ErrorCode != "" {
+			fmt.Fprintf(&sb, " (%s", d.ErrorCode)
+			if d.ErrorStep != "" {
+				fmt.Fprintf(&sb, " during %s", d.ErrorStep)
+			}
+			sb.WriteString(")")
+		}
+		sb.WriteString("\n")
+	}
+
+	out, err := o.api.RunAPIWithContext(ctx, "GET", "/v2/deployments/"+url.PathEscape(d.UID)+"/events?builds=1&limit=-1", "")
+	if err != nil {
+		fmt.Fprintf(&sb, "  Build output unavailable: %v\n", err)
+		return sb.String(), nil
+	}
+	lines := buildLines(out)
+	if len(lines) == 0 {
+		sb.WriteString("  No build output returned.\n")
+		return sb.String(), nil
+	}
+	if len(lines) > buildLogTail {
+		fmt.Fprintf(&sb, "  Build output (last %d of %d lines):\n", buildLogTail, len(lines))
+		lines = lines[len(lines)-buildLogTail:]
+	} else {
+		sb.WriteString("  Build output:\n")
+	}
+	for _, line := range lines {
+		fmt.Fprintf(&sb, "    %s\n", line)
+	}
+	return sb.String(), nil
+}
+
+// buildLines returns the text of a deployment's build events in order.
+func buildLines(body string) []string {
+	type event struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Text string `json:"text,omitempty"`
+		} `json:"payload,omitempty"`
+		Text string `json:"text,omitempty"`
+	}
+	var events []event
+	if err := json.Unmarshal([]byte(strings.TrimSpace(body)), &events); err != nil {
+		var envelope struct {
+			Events []event `json:"events"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(body)), &envelope); err != nil {
+			return nil
+		}
+		events = envelope.Events
+	}
+	var lines []string
+	for _, e := range events {
+		text := e.Payload.Text
+		if text == "" {
+			text = e.Text
+		}
+		for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			if strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	return lines
+}
+
+func (o *Ops) formatProjects(projects []Project) string {
+	if len(projects) == 0 {
+		return "No Vercel projects found.\n"
+	}
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tFRAMEWORK\tREPO\tUPDATED")
+	for _, p := range projects {
+		repo := "-"
+		if p.Link != nil && p.Link.Repo != "" {
+			repo = p.Link.Repo
+		}
+		framework := p.Framework
+		if framework == "" {
+			framework = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.Name, framework, repo, o.age(p.UpdatedAt))
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+func (o *Ops) formatDeployments(deployments []Deployment) string {
+	if len(deployments) == 0 {
+		return "No deployments found.\n"
+	}
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGE\tPROJECT\tSTATE\tTARGET\tURL\tCOMMIT")
+	for _, d := range deployments {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", o.age(d.Created), d.Name, deploymentState(d), deploymentTarget(d), d.URL, firstLine(metaString(d, "githubCommitMessage")))
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+func formatEnv(project string, envs []EnvVar) string {
+	if len(envs) == 0 {
+		return fmt.Sprintf("No environment variables for project %s.\n", project)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Environment variables of %s (names only):\n", project)
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  KEY\tTYPE\tTARGETS")
+	for _, e := range envs {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", e.Key, e.Type, strings.Join(e.Target, ","))
+	}
+	tw.Flush()
+	return sb.String()
+}
+
+// age renders a millisecond timestamp as a coarse age
+func (o *Ops) age(ms int64) string {
+	if ms <= 0 {
+		return "-"
+	}
+	d := o.now.Sub(time.UnixMilli(ms))
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+func deploymentState(d Deployment) string {
+	if d.State != "" {
+		return d.State
+	}
+	if d.ReadyState != "" {
+		return d.ReadyState
+	}
+	return "-"
+}
+
+func deploymentTarget(d Deployment) string {
+	if d.Target == "" {
+		return "preview"
+	}
+	return d.Target
+}
+
+func metaString(d Deployment, key string) string {
+	s, _ := d.Meta[key].(string)
+	return s
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
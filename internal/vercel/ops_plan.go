@@ -0,0 +1,98 @@
+package vercel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/maker"
+)
+
+// SecretVariablePrefix prefixes the environment variables an env plan reads
+// its values from at apply time, e.g. VERCEL_SECRET_DATABASE_URL, so a value
+// is never written to a plan.
+const SecretVariablePrefix = "VERCEL_SECRET_"
+
+// OpPlan is a change planned from an OpRequest, with the notes the caller
+// prints next to it.
+type OpPlan struct {
+	Plan  *maker.Plan
+	Notes []string
+}
+
+// BuildOpPlan plans a redeploy or an environment variable change as vercel
+// CLI commands. A redeploy rebuilds latest, the deployment the caller
+// looked up for the project.
+func BuildOpPlan(req OpRequest, latest *Deployment, question string, now time.Time) (*OpPlan, error) {
+	if req.Project == "" {
+		return nil, fmt.Errorf("name the Vercel project to change, e.g. \"project my-site\"")
+	}
+	out := &OpPlan{}
+	var summary string
+	var cmds []maker.Command
+
+	target := req.Target
+	if target == "" {
+		target = "production"
+	}
+
+	switch req.Op {
+	case OpRedeploy:
+		if latest == nil {
+			return nil, fmt.Errorf("no deployment of %s to redeploy", req.Project)
+		}
+		ref := latest.URL
+		if ref == "" {
+			ref = latest.UID
+		}
+		summary = fmt.Sprintf("Redeploy %s from deployment %s", req.Project, latest.UID)
+		cmds = append(cmds, maker.Command{
+			Args:   []string{"vercel", "redeploy", ref},
+			Reason: fmt.Sprintf("Rebuild the latest %s deployment of %s (%s)", deploymentTarget(*latest), req.Project, deploymentState(*latest)),
+		})
+		if deploymentState(*latest) == "ERROR" {
+			out.Notes = append(out.Notes, "The latest deployment failed. A redeploy rebuilds the same commit, so it only helps when the failure was transient or an environment variable has since been fixed.")
+		}
+	case OpSetEnv:
+		if len(req.Vars) == 0 {
+			return nil, fmt.Errorf("name the variables to set in capitals, e.g. DATABASE_URL")
+		}
+		var vars []string
+		for _, name := range req.Vars {
+			// The executor pipes the value at index 4 to the CLI's stdin
+			cmds = append(cmds, maker.Command{
+				Args:   []string{"vercel", "env", "add", name, "<" + SecretVariablePrefix + name + ">", target, "--project", req.Project, "--force"},
+				Reason: fmt.Sprintf("Set %s for %s deployments of %s", name, target, req.Project),
+			})
+			vars = append(vars, SecretVariablePrefix+name)
+		}
+		summary = fmt.Sprintf("Set %s on %s (%s)", strings.Join(req.Vars, ", "), req.Project, target)
+		out.Notes = append(out.Notes,
+			fmt.Sprintf("Export %s before applying. Values are piped to the vercel CLI at apply time and never written to the plan.", strings.Join(vars, ", ")),
+			"New values apply to the next deployment; ask to redeploy the project to pick them up now.")
+	case OpUnsetEnv:
+		if len(req.Vars) == 0 {
+			return nil, fmt.Errorf("name the variables to remove in capitals, e.g. DATABASE_URL")
+		}
+		for _, name := range req.Vars {
+			cmds = append(cmds, maker.Command{
+				Args:   []string{"vercel", "env", "rm", name, target, "--yes", "--project", req.Project},
+				Reason: fmt.Sprintf("Remove %s from %s deployments of %s", name, target, req.Project),
+			})
+		}
+		summary = fmt.Sprintf("Remove %s from %s (%s)", strings.Join(req.Vars, ", "), req.Project, target)
+		out.Notes = append(out.Notes, "Removing variables is destructive; apply with --destroyer.")
+	default:
+		return nil, fmt.Errorf("%s does not change anything", req.Op)
+	}
+
+	out.Plan = &maker.Plan{
+		Version:   maker.CurrentPlanVersion,
+		CreatedAt: now.UTC(),
+		Provider:  "vercel",
+		Question:  question,
+		Summary:   summary,
+		Commands:  cmds,
+	}
+	return out, nil
+}
@@ -0,0 +1,124 @@
+package vercel
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/bgdnvk/clanker/internal/askparse"
+)
+
+// Op is a Vercel operation an ask question can be answered with directly,
+// without going through the LLM context agent.
+type Op string
+
+const (
+	OpListProjects    Op = "list-projects"
+	OpListDeployments Op = "list-deployments"
+	OpBuildLogs       Op = "build-logs"
+	OpListEnv         Op = "list-env"
+	OpRedeploy        Op = "redeploy"
+	OpSetEnv          Op = "set-env"
+	OpUnsetEnv        Op = "unset-env"
+)
+
+// Mutates reports whether the op changes the project, in which case it is
+// planned rather than run.
+func (o Op) Mutates() bool {
+	switch o {
+	case OpRedeploy, OpSetEnv, OpUnsetEnv:
+		return true
+	}
+	return false
+}
+
+// OpRequest is a question translated into one Vercel operation.
+type OpRequest struct {
+	Op      Op
+	Project string
+	// Target is production, preview or development, empty when the
+	// question names none.
+	Target string
+	// Failed asks for the latest failed deployment rather than the latest.
+	Failed bool
+	// Vars are the environment variable names to set or remove. Values are
+	// never read from the question.
+	Vars []string
+}
+
+var (
+	opProjectNames = askparse.Names{
+		Patterns: []*regexp.Regexp{
+			regexp.MustCompile(`(?:^|\s)--project[\s=]+([a-z0-9][a-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\bproject\s+(?:named\s+|called\s+)?["'` + "`" + `]?([a-z0-9][a-z0-9._-]*)`),
+			regexp.MustCompile(`(?i)\b([a-z0-9][a-z0-9._-]*)\s+(?:vercel\s+)?project\b`),
+		},
+		Skip: askparse.NotNames("each", "every", "vercel", "latest", "last", "env", "production", "preview"),
+	}
+
+	opEnvRe      = regexp.MustCompile(`(?i)\benv(?:ironment)?\s+var(?:iable)?s?\b|\benvs?\b`)
+	opSetRe      = regexp.MustCompile(`(?i)\b(?:set|add|create|update|rotate|put|change)\b`)
+	opUnsetRe    = regexp.MustCompile(`(?i)\b(?:unset|remove|delete|drop)\b`)
+	opRedeployRe = regexp.MustCompile(`(?i)\bre-?deploy\b|\btrigger\b.*\bdeploy(?:ment)?\b`)
+	opLogsRe     = regexp.MustCompile(`(?i)\bbuild\s+(?:logs?|output)\b|\blogs?\b`)
+	opDeploysRe  = regexp.MustCompile(`(?i)\bdeployments\b|\bdeploys\b`)
+	opProjectsRe = regexp.MustCompile(`(?i)\bprojects\b`)
+	opReadRe     = regexp.MustCompile(`(?i)\b(?:list|show|get|what|which|how\s+many|recent|latest|last)\b`)
+	opFailedRe   = regexp.MustCompile(`(?i)\bfail(?:ed|ing|ure)?\b|\berror(?:ed)?\b|\bbroke(?:n)?\b`)
+	// opWhyFailedRe asks why a deployment failed
+	opWhyFailedRe = regexp.MustCompile(`(?i)\bwhy\b.*\b(?:fail(?:ed|ing|s)?|error(?:ed)?|broke|crash(?:ed|ing)?)\b|\bwhat\s+(?:broke|went\s+wrong)\b`)
+	opTargetRe    = regexp.MustCompile(`(?i)\b(production|prod|preview|development|dev)\b`)
+)
+
+// ParseOpRequest reads the Vercel operation question asks for. The zero
+// OpRequest means the question is better answered by the context agent.
+func ParseOpRequest(question string) OpRequest {
+	req := OpRequest{Project: opProject(question), Failed: opFailedRe.MatchString(question)}
+	if m := opTargetRe.FindStringSubmatch(question); m != nil {
+		switch strings.ToLower(m[1]) {
+		case "production", "prod":
+			req.Target = "production"
+		case "preview":
+			req.Target = "preview"
+		default:
+			req.Target = "development"
+		}
+	}
+	switch {
+	case opWhyFailedRe.MatchString(question):
+		// Answered by the context agent with the failed build's output
+	case opEnvRe.MatchString(question):
+		req.Vars = askparse.KeyNames(question)
+		switch {
+		case opUnsetRe.MatchString(question):
+			req.Op = OpUnsetEnv
+		case opSetRe.MatchString(question):
+			req.Op = OpSetEnv
+		case req.Project != "":
+			req.Op = OpListEnv
+		}
+	case opRedeployRe.MatchString(question):
+		req.Op = OpRedeploy
+	case opLogsRe.MatchString(question):
+		req.Op = OpBuildLogs
+	case opDeploysRe.MatchString(question) && opReadRe.MatchString(question):
+		req.Op = OpListDeployments
+	case opProjectsRe.MatchString(question) && opReadRe.MatchString(question):
+		req.Op = OpListProjects
+	}
+	if req.Op == "" || (req.Op.Mutates() && askparse.AsksHowTo(question)) {
+		return OpRequest{}
+	}
+	return req
+}
+
+// IsFailedDeployQuestion reports whether question asks why a deployment
+// failed, so its build output belongs in the context.
+func IsFailedDeployQuestion(question string) bool {
+	return opWhyFailedRe.MatchString(question)
+}
+
+// opProject returns the project question names, lower-cased as Vercel
+// stores project names
+func opProject(question string) string {
+	return strings.ToLower(opProjectNames.Find(question))
+}
@@ -0,0 +1,167 @@
+package vercel
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+// fakeOpsAPI answers API calls by method and endpoint
+type fakeOpsAPI struct {
+	api map[string]string
+}
+
+func (f *fakeOpsAPI) RunAPIWithContext(ctx context.Context, method, endpoint, body string) (string, error) {
+	if out, ok := f.api[method+" "+endpoint]; ok {
+		return out, nil
+	}
+	return "", errors.New("unexpected call: " + method + " " + endpoint)
+}
+
+func TestParseOpRequest(t *testing.T) {
+	tests := []struct {
+		question string
+		want     OpRequest
+	}{
+		{"list my vercel projects", OpRequest{Op: OpListProjects}},
+		{"show recent deployments for project my-site", OpRequest{Op: OpListDeployments, Project: "my-site"}},
+		{"show build logs for the latest failed production deployment of project my-site", OpRequest{Op: OpBuildLogs, Project: "my-site", Target: "production", Failed: true}},
+		{"list env vars for the docs vercel project", OpRequest{Op: OpListEnv, Project: "docs"}},
+		{"set DATABASE_URL and API_KEY env vars on project my-site for preview", OpRequest{Op: OpSetEnv, Project: "my-site", Target: "preview", Vars: []string{"DATABASE_URL", "API_KEY"}}},
+		{"remove env var OLD_TOKEN from project my-site", OpRequest{Op: OpUnsetEnv, Project: "my-site", Vars: []string{"OLD_TOKEN"}}},
+		{"redeploy project my-site", OpRequest{Op: OpRedeploy, Project: "my-site"}},
+	}
+	for _, tt := range tests {
+		if got := ParseOpRequest(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseOpRequest(%q) = %+v, want %+v", tt.question, got, tt.want)
+		}
+	}
+
+	for _, q := range []string{
+		"why did my last vercel deploy fail",
+		"how do I redeploy on vercel",
+		"list vercel env vars",
+	} {
+		if got := ParseOpRequest(q); got.Op != "" {
+			t.Errorf("ParseOpRequest(%q) = %+v, want it left to the context agent", q, got)
+		}
+	}
+	if !IsFailedDeployQuestion("why did my last vercel deploy fail") {
+		t.Error("a why-did-it-fail question should pull in the failed build")
+	}
+}
+
+func TestOpsReadDeploymentsAndEnv(t *testing.T) {
+	api := &fakeOpsAPI{api: map[string]string{
+		"GET /v6/deployments?limit=20&projectId=my-site": `{"deployments": [
+			{"uid": "dpl_1", "name": "my-site", "url": "my-site-abc.vercel.app", "state": "ERROR", "target": "production",
+			 "created": 1792058400000, "meta": {"githubCommitMessage": "bump next\n\nbody", "githubPrId": 12}}]}`,
+		"GET /v10/projects/my-site/env": `{"envs": [{"key": "DATABASE_URL", "value": "postgres://secret", "type": "encrypted", "target": ["production", "preview"]}]}`,
+	}}
+	ops := NewOps(api, testNow)
+	out, err := ops.Read(context.Background(), OpRequest{Op: OpListDeployments, Project: "my-site"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"2h", "ERROR", "production", "my-site-abc.vercel.app", "bump next"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("deployments output missing %q:\n%s", want, out)
+		}
+	}
+
+	out, err = ops.Read(context.Background(), OpRequest{Op: OpListEnv, Project: "my-site"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "DATABASE_URL  encrypted  production,preview") || strings.Contains(out, "postgres://") {
+		t.Errorf("env output should list names only:\n%s", out)
+	}
+}
+
+func TestFailedDeploymentContext(t *testing.T) {
+	var events strings.Builder
+	events.WriteString("[")
+	for i := 0; i < 50; i++ {
+		if i > 0 {
+			events.WriteString(",")
+		}
+		events.WriteString(`{"type": "stdout", "payload": {"text": "step ` + string(rune('a'+i%26)) + `"}}`)
+	}
+	events.WriteString(`,{"type": "stderr", "payload": {"text": "Error: Cannot find module 'next'"}}]`)
+
+	api := &fakeOpsAPI{api: map[string]string{
+		"GET /v6/deployments?limit=1&projectId=my-site&state=ERROR": `{"deployments": [{"uid": "dpl_1", "name": "my-site", "state": "ERROR", "target": "production"}]}`,
+		"GET /v13/deployments/dpl_1": `{"id": "dpl_1", "errorCode": "BUILD_FAILED", "errorMessage": "Command \"npm run build\" exited with 1", "errorStep": "build",
+			"meta": {"githubCommitRef": "main", "githubCommitMessage": "bump next"}}`,
+		"GET /v2/deployments/dpl_1/events?builds=1&limit=-1": events.String(),
+	}}
+	out, err := NewOps(api, testNow).FailedDeploymentContext(context.Background(), "my-site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Latest Failed Deployment:",
+		"Deployment dpl_1 of my-site (ERROR, production)",
+		"Commit: main bump next",
+		`exited with 1 (BUILD_FAILED during build)`,
+		"last 40 of 51 lines",
+		"Cannot find module 'next'",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestBuildOpPlan(t *testing.T) {
+	latest := &Deployment{UID: "dpl_1", URL: "my-site-abc.vercel.app", State: "ERROR", Target: "production"}
+	tests := []struct {
+		req      OpRequest
+		wantArgs [][]string
+		summary  string
+	}{
+		{
+			OpRequest{Op: OpRedeploy, Project: "my-site"},
+			[][]string{{"vercel", "redeploy", "my-site-abc.vercel.app"}},
+			"Redeploy my-site from deployment dpl_1",
+		},
+		{
+			OpRequest{Op: OpSetEnv, Project: "my-site", Target: "preview", Vars: []string{"DATABASE_URL"}},
+			[][]string{{"vercel", "env", "add", "DATABASE_URL", "<VERCEL_SECRET_DATABASE_URL>", "preview", "--project", "my-site", "--force"}},
+			"Set DATABASE_URL on my-site (preview)",
+		},
+		{
+			OpRequest{Op: OpUnsetEnv, Project: "my-site", Vars: []string{"OLD_TOKEN"}},
+			[][]string{{"vercel", "env", "rm", "OLD_TOKEN", "production", "--yes", "--project", "my-site"}},
+			"Remove OLD_TOKEN from my-site (production)",
+		},
+	}
+	for _, tt := range tests {
+		out, err := BuildOpPlan(tt.req, latest, "q", testNow)
+		if err != nil {
+			t.Fatalf("BuildOpPlan(%+v): %v", tt.req, err)
+		}
+		var args [][]string
+		for _, c := range out.Plan.Commands {
+			args = append(args, c.Args)
+		}
+		if !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("BuildOpPlan(%+v) args = %v, want %v", tt.req, args, tt.wantArgs)
+		}
+		if out.Plan.Provider != "vercel" || out.Plan.Summary != tt.summary {
+			t.Errorf("plan = %q %q, want vercel %q", out.Plan.Provider, out.Plan.Summary, tt.summary)
+		}
+	}
+
+	if _, err := BuildOpPlan(OpRequest{Op: OpSetEnv, Vars: []string{"A_B"}}, nil, "q", testNow); err == nil {
+		t.Error("an env change with no project should fail")
+	}
+	if _, err := BuildOpPlan(OpRequest{Op: OpRedeploy, Project: "my-site"}, nil, "q", testNow); err == nil {
+		t.Error("a redeploy with no deployment should fail")
+	}
+}
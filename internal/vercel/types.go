@@ -42,6 +42,14 @@ type Deployment struct {
 		Username string `json:"username"`
 		Email    string `json:"email,omitempty"`
 	} `json:"creator,omitempty"`
+	// Meta carries the git source, e.g. githubCommitRef and
+	// githubCommitMessage.
+	Meta map[string]any `json:"meta,omitempty"`
+	// Error fields are only set on failed deployments, and only by
+	// /v13/deployments/{id}.
+	ErrorCode    string `json:"errorCode,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+	ErrorStep    string `json:"errorStep,omitempty"`
 }
 
 // Domain represents a custom domain.
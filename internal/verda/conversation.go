@@ -1,38 +1,14 @@
 package verda
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/bgdnvk/clanker/internal/secfile"
+	"github.com/bgdnvk/clanker/internal/convhistory"
 )
 
-// fileLocks serializes Save+Load per ScopeID so two concurrent
-// `clanker verda ask` invocations for the same project don't race on the
-// tmp-file + rename dance and lose one conversation's history. Keyed by the
-// sanitized filename so two scopes that happen to normalise to the same name
-// still share a lock.
-var (
-	fileLocks   = map[string]*sync.Mutex{}
-	fileLocksMu sync.Mutex
-)
-
-func fileLockFor(name string) *sync.Mutex {
-	fileLocksMu.Lock()
-	defer fileLocksMu.Unlock()
-	if m, ok := fileLocks[name]; ok {
-		return m
-	}
-	m := &sync.Mutex{}
-	fileLocks[name] = m
-	return m
-}
-
 // ConversationEntry is a single Q&A exchange.
 type ConversationEntry struct {
 	Timestamp time.Time `json:"timestamp"`
@@ -70,15 +46,11 @@ func (h *ConversationHistory) AddEntry(question, answer string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	h.Entries = append(h.Entries, ConversationEntry{
+	h.Entries = convhistory.Append(h.Entries, ConversationEntry{
 		Timestamp: time.Now(),
 		Question:  question,
 		Answer:    answer,
-	})
-
-	if len(h.Entries) > MaxHistoryEntries {
-		h.Entries = h.Entries[len(h.Entries)-MaxHistoryEntries:]
-	}
+	}, MaxHistoryEntries)
 }
 
 // GetRecentContext returns a compact string of recent exchanges for the LLM prompt.
@@ -101,115 +73,23 @@ func (h *ConversationHistory) GetRecentContext(maxEntries int) string {
 			sb.WriteString("\n")
 		}
 		sb.WriteString(fmt.Sprintf("Q: %s\n", entry.Question))
-		sb.WriteString(fmt.Sprintf("A: %s\n", truncate(entry.Answer, MaxAnswerLengthInContext)))
+		sb.WriteString(fmt.Sprintf("A: %s\n", convhistory.Truncate(entry.Answer, MaxAnswerLengthInContext)))
 	}
 	return sb.String()
 }
 
-// Save persists the conversation to ~/.clanker/conversations/verda_<scope>.json.
-// Write is atomic: marshal → write temp file → rename over the destination.
-// A crash mid-write leaves either the old or new file intact but never a
-// half-written blob that would poison subsequent loads. A per-scope file lock
-// serializes concurrent Save calls for the same project — without it, two
-// parallel `ask --verda` calls could race on the rename and drop one history.
+// Save persists the conversation history to ~/.clanker/conversations/verda_<scopeid>.json
 func (h *ConversationHistory) Save() error {
-	h.mu.RLock()
-	data, err := json.MarshalIndent(h, "", "  ")
-	scopeName := secfile.SafeSlug(h.ScopeID)
-	h.mu.RUnlock()
-	if err != nil {
-		return fmt.Errorf("marshal history: %w", err)
-	}
-
-	lock := fileLockFor(scopeName)
-	lock.Lock()
-	defer lock.Unlock()
-
-	dir, err := conversationDir()
-	if err != nil {
-		return err
-	}
-	if err := secfile.EnsurePrivateDir(dir); err != nil {
-		return fmt.Errorf("create conversation dir: %w", err)
-	}
-
-	path := filepath.Join(dir, fmt.Sprintf("verda_%s.json", scopeName))
-	// os.CreateTemp creates files at 0o600 on Unix by default — Rename
-	// below preserves that, so the persisted file ends up private.
-	tmp, err := os.CreateTemp(dir, "verda_*.json.tmp")
-	if err != nil {
-		return fmt.Errorf("create tmp: %w", err)
-	}
-	tmpName := tmp.Name()
-	if _, err := tmp.Write(data); err != nil {
-		tmp.Close()
-		_ = os.Remove(tmpName)
-		return fmt.Errorf("write tmp: %w", err)
-	}
-	if err := tmp.Close(); err != nil {
-		_ = os.Remove(tmpName)
-		return fmt.Errorf("close tmp: %w", err)
-	}
-	if err := os.Rename(tmpName, path); err != nil {
-		_ = os.Remove(tmpName)
-		return fmt.Errorf("rename: %w", err)
-	}
-	return nil
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Entries = convhistory.FitSize(h.Entries, convhistory.MaxBytes())
+	return convhistory.Save("verda", h.ScopeID, h)
 }
 
-// Load restores history from disk. Missing file is not an error.
+// Load loads conversation history from disk
 func (h *ConversationHistory) Load() error {
-	// Take the per-scope file lock first so we don't read a file that Save
-	// is currently mid-rename on. Grabbing the struct mutex early would let
-	// a parallel Save hold fileLock + block here, so the order is:
-	// fileLock (cross-process write barrier) → struct mu (in-memory state).
-	scopeName := secfile.SafeSlug(h.ScopeID)
-	lock := fileLockFor(scopeName)
-	lock.Lock()
-	defer lock.Unlock()
-
 	h.mu.Lock()
 	defer h.mu.Unlock()
-
-	dir, err := conversationDir()
-	if err != nil {
-		return err
-	}
-	path := filepath.Join(dir, fmt.Sprintf("verda_%s.json", scopeName))
-	data, err := secfile.ReadPrivate(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return fmt.Errorf("read conversation: %w", err)
-	}
-
-	var loaded struct {
-		Entries []ConversationEntry `json:"entries"`
-		ScopeID string              `json:"scope_id"`
-	}
-	if err := json.Unmarshal(data, &loaded); err != nil {
-		return fmt.Errorf("parse conversation: %w", err)
-	}
-
-	h.Entries = loaded.Entries
-	if loaded.ScopeID != "" {
-		h.ScopeID = loaded.ScopeID
-	}
-	return nil
-}
-
-func conversationDir() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-	return filepath.Join(home, ".clanker", "conversations"), nil
-}
-
-func truncate(s string, max int) string {
-	if len(s) <= max {
-		return s
-	}
-	return s[:max] + "..."
+	_, err := convhistory.Load("verda", h.ScopeID, h)
+	return err
 }
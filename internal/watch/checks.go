@@ -0,0 +1,251 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/certs"
+	"github.com/bgdnvk/clanker/internal/cost"
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+)
+
+// maxDetails is how many findings a result lists
+const maxDetails = 10
+
+// Defaults for checks that leave their thresholds unset
+const (
+	DefaultCertDays      = 30
+	DefaultCostThreshold = 20.0
+	// certCriticalDays is when an expiring certificate is critical, even
+	// one that should renew on its own
+	certCriticalDays = 7
+)
+
+// HealthAPI is the part of the SRE health checker cluster-health uses
+type HealthAPI interface {
+	CheckClusterDetailed(ctx context.Context) (*sre.ClusterHealthSummary, []sre.Issue, error)
+	CheckNamespace(ctx context.Context, namespace string) (*sre.HealthCheckResult, error)
+}
+
+// ClusterHealth checks the cluster, or one namespace, with the health
+// checker for the check's kubeconfig context. A critical cluster or a
+// critical issue is critical; a degraded cluster or any warning is a
+// warning.
+func ClusterHealth(health func(kubeContext string) HealthAPI) Checker {
+	return func(ctx context.Context, check CheckConfig) (Result, error) {
+		api := health(check.Context)
+		var issues []sre.Issue
+		var result Result
+		if check.Namespace != "" {
+			ns, err := api.CheckNamespace(ctx, check.Namespace)
+			if err != nil {
+				return Result{}, err
+			}
+			issues = ns.Issues
+			result = Result{Status: StatusOK, Summary: fmt.Sprintf("namespace %s score %d/100", check.Namespace, ns.Score)}
+			if !ns.Healthy {
+				result.Status = StatusWarning
+			}
+		} else {
+			summary, found, err := api.CheckClusterDetailed(ctx)
+			if err != nil {
+				return Result{}, err
+			}
+			issues = found
+			result = Result{Status: StatusOK, Summary: fmt.Sprintf("cluster %s, score %d/100, %d critical and %d warning issues",
+				summary.OverallHealth, summary.Score, summary.CriticalIssues, summary.WarningIssues)}
+			switch summary.OverallHealth {
+			case "critical":
+				result.Status = StatusCritical
+			case "degraded":
+				result.Status = StatusWarning
+			}
+		}
+		for _, issue := range issues {
+			switch issue.Severity {
+			case sre.SeverityCritical:
+				result.Status = worse(result.Status, StatusCritical)
+			case sre.SeverityWarning:
+				result.Status = worse(result.Status, StatusWarning)
+			default:
+				continue
+			}
+			result.Details = appendDetail(result.Details, fmt.Sprintf("%s %s/%s: %s",
+				strings.ToUpper(string(issue.Severity)), issue.ResourceType, qualified(issue.Namespace, issue.ResourceName), issue.Message))
+		}
+		return result, nil
+	}
+}
+
+// CertExpiry lists the certificates of the check's sources. A certificate
+// with certCriticalDays or fewer left is critical; one within the check's
+// Days that will not renew on its own is a warning. A source that cannot
+// be read makes the check an error only when no source could be read.
+func CertExpiry(list func(ctx context.Context, profile string, sources []certs.Source) *certs.Report, now func() time.Time) Checker {
+	return func(ctx context.Context, check CheckConfig) (Result, error) {
+		days := check.Days
+		if days <= 0 {
+			days = DefaultCertDays
+		}
+		var sources []certs.Source
+		for _, s := range check.Sources {
+			sources = append(sources, certs.Source(strings.ToLower(strings.TrimSpace(s))))
+		}
+		report := list(ctx, check.Profile, sources)
+		if len(report.Errors) > 0 && len(report.Errors) == len(report.Sources) {
+			var msgs []string
+			for _, s := range report.Sources {
+				msgs = append(msgs, fmt.Sprintf("%s: %v", s.Label(), report.Errors[s]))
+			}
+			return Result{}, fmt.Errorf("no certificate source could be read (%s)", strings.Join(msgs, "; "))
+		}
+
+		t := now()
+		result := Result{Status: StatusOK}
+		expiring := 0
+		for _, c := range report.Certs {
+			if c.NotAfter.IsZero() {
+				continue
+			}
+			left := c.DaysLeft(t)
+			status := StatusOK
+			switch {
+			case left <= certCriticalDays:
+				status = StatusCritical
+			case left <= days && !c.AutoRenew:
+				status = StatusWarning
+			}
+			if status == StatusOK {
+				continue
+			}
+			expiring++
+			result.Status = worse(result.Status, status)
+			when := fmt.Sprintf("expires in %d days", left)
+			if left < 0 {
+				when = fmt.Sprintf("expired %d days ago", -left)
+			}
+			result.Details = appendDetail(result.Details, fmt.Sprintf("%s %s: %s", c.Source.Label(), c.Location(), when))
+		}
+		result.Summary = fmt.Sprintf("%d of %d certificates need attention within %d days", expiring, len(report.Certs), days)
+		for _, s := range report.Sources {
+			if err := report.Errors[s]; err != nil {
+				result.Details = append(result.Details, fmt.Sprintf("%s could not be read: %v", s.Label(), err))
+			}
+		}
+		return result, nil
+	}
+}
+
+// CostAnomaly reports the cost anomalies whose change is at least the
+// check's Threshold percent. Twice the threshold is critical.
+func CostAnomaly(anomalies func(ctx context.Context, profile string) ([]cost.CostAnomaly, error)) Checker {
+	return func(ctx context.Context, check CheckConfig) (Result, error) {
+		threshold := check.Threshold
+		if threshold <= 0 {
+			threshold = DefaultCostThreshold
+		}
+		found, err := anomalies(ctx, check.Profile)
+		if err != nil {
+			return Result{}, err
+		}
+		result := Result{Status: StatusOK}
+		count := 0
+		for _, a := range found {
+			if a.PercentChange < threshold {
+				continue
+			}
+			count++
+			status := StatusWarning
+			if a.PercentChange >= 2*threshold {
+				status = StatusCritical
+			}
+			result.Status = worse(result.Status, status)
+			result.Details = appendDetail(result.Details, fmt.Sprintf("%s: $%.2f vs $%.2f expected (+%.0f%%)",
+				a.Service, a.ActualCost, a.ExpectedCost, a.PercentChange))
+		}
+		result.Summary = fmt.Sprintf("%d cost anomalies of %.0f%% or more", count, threshold)
+		return result, nil
+	}
+}
+
+// Runner runs a CLI command and returns its output
+type Runner func(ctx context.Context, args []string) (string, error)
+
+// FailingWorkflows runs gh against the check's Repo and reports every
+// workflow whose latest completed run on Branch failed. Branch defaults to
+// every branch's latest run per workflow.
+func FailingWorkflows(gh Runner) Checker {
+	return func(ctx context.Context, check CheckConfig) (Result, error) {
+		args := []string{"run", "list", "--limit", "50", "--json", "workflowName,headBranch,status,conclusion,url"}
+		if check.Repo != "" {
+			args = append(args, "-R", check.Repo)
+		}
+		if check.Branch != "" {
+			args = append(args, "--branch", check.Branch)
+		}
+		out, err := gh(ctx, args)
+		if err != nil {
+			return Result{}, err
+		}
+		var runs []struct {
+			WorkflowName string `json:"workflowName"`
+			HeadBranch   string `json:"headBranch"`
+			Status       string `json:"status"`
+			Conclusion   string `json:"conclusion"`
+			URL          string `json:"url"`
+		}
+		if err := json.Unmarshal([]byte(out), &runs); err != nil {
+			return Result{}, fmt.Errorf("failed to parse gh run list output: %w", err)
+		}
+
+		// gh lists the newest run first, so the first completed run of a
+		// workflow on a branch is its latest
+		latest := map[string]bool{}
+		var failing []string
+		for _, r := range runs {
+			if r.Status != "completed" {
+				continue
+			}
+			key := r.WorkflowName + "@" + r.HeadBranch
+			if latest[key] {
+				continue
+			}
+			latest[key] = true
+			switch r.Conclusion {
+			case "failure", "timed_out", "startup_failure":
+				failing = append(failing, fmt.Sprintf("%s on %s: %s %s", r.WorkflowName, r.HeadBranch, r.Conclusion, r.URL))
+			}
+		}
+
+		result := Result{Status: StatusOK, Summary: fmt.Sprintf("%d of %d workflows failing", len(failing), len(latest))}
+		if len(failing) > 0 {
+			result.Status = StatusCritical
+		}
+		for _, f := range failing {
+			result.Details = appendDetail(result.Details, f)
+		}
+		return result, nil
+	}
+}
+
+// appendDetail adds detail until maxDetails are listed, then notes that
+// there are more
+func appendDetail(details []string, detail string) []string {
+	switch {
+	case len(details) < maxDetails:
+		return append(details, detail)
+	case len(details) == maxDetails:
+		return append(details, "...and more")
+	}
+	return details
+}
+
+func qualified(namespace, name string) string {
+	if namespace == "" {
+		return name
+	}
+	return namespace + "/" + name
+}
@@ -0,0 +1,115 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/notify"
+)
+
+// Notifier announces a status change
+type Notifier interface {
+	Notify(ctx context.Context, t Transition) error
+}
+
+// NewNotifiers builds the notifiers of a config, writing stdout
+// notifications to out. A config without any notifies on stdout.
+func NewNotifiers(configs []NotifyConfig, out io.Writer) []Notifier {
+	if len(configs) == 0 {
+		return []Notifier{&Stdout{Out: out}}
+	}
+	client := &http.Client{Timeout: 15 * time.Second}
+	notifiers := make([]Notifier, 0, len(configs))
+	for _, c := range configs {
+		switch c.Type {
+		case "webhook":
+			notifiers = append(notifiers, &Webhook{URL: c.URL, Client: client})
+		case "slack":
+			notifiers = append(notifiers, &Chat{Sender: &notify.Slack{URL: c.URL, Client: client}})
+		case "discord":
+			notifiers = append(notifiers, &Chat{Sender: &notify.Discord{URL: c.URL, Client: client}})
+		default:
+			notifiers = append(notifiers, &Stdout{Out: out})
+		}
+	}
+	return notifiers
+}
+
+// Text renders a transition as a short message
+func (t Transition) Text() string {
+	var sb strings.Builder
+	from := string(t.From)
+	if from == "" {
+		from = "new"
+	}
+	fmt.Fprintf(&sb, "[%s] %s: %s -> %s: %s", t.At.Format(time.RFC3339), t.Check, from, strings.ToUpper(string(t.To)), t.Summary)
+	for _, d := range t.Details {
+		fmt.Fprintf(&sb, "\n  - %s", d)
+	}
+	return sb.String()
+}
+
+// Stdout prints transitions
+type Stdout struct {
+	Out io.Writer
+}
+
+func (s *Stdout) Notify(ctx context.Context, t Transition) error {
+	_, err := fmt.Fprintln(s.Out, t.Text())
+	return err
+}
+
+// Webhook POSTs each transition as JSON
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+func (w *Webhook) Notify(ctx context.Context, t Transition) error {
+	return postJSON(ctx, w.Client, w.URL, t)
+}
+
+// Chat posts each transition as a chat message through a Slack or
+// Discord sender, or the notify sinks of the config file
+type Chat struct {
+	Sender notify.Sender
+}
+
+func (c *Chat) Notify(ctx context.Context, t Transition) error {
+	return c.Sender.Send(ctx, notify.WatchAlert(notify.Alert{
+		Check:   t.Check,
+		Type:    t.Type,
+		From:    string(t.From),
+		To:      string(t.To),
+		Summary: t.Summary,
+		Details: t.Details,
+	}))
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("notification returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+// Package watch runs configured checks on intervals and notifies when a
+// check changes status. The last status of every check is kept in a state
+// file, so a restart does not announce what was already known and a check
+// that stays red does not notify again on every run.
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/secfile"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultInterval is how often a check runs when neither it nor the config
+// sets an interval
+const DefaultInterval = 5 * time.Minute
+
+// minInterval keeps a typo like "5s" on a Cost Explorer check from running
+// up API charges
+const minInterval = 30 * time.Second
+
+// Check types
+const (
+	TypeClusterHealth    = "cluster-health"
+	TypeCertExpiry       = "cert-expiry"
+	TypeCostAnomaly      = "cost-anomaly"
+	TypeFailingWorkflows = "failing-workflows"
+)
+
+// Status is the outcome of one check run
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusWarning  Status = "warning"
+	StatusCritical Status = "critical"
+	// StatusError means the check itself could not run
+	StatusError Status = "error"
+)
+
+// Config is a checks file
+type Config struct {
+	// Interval is the default for checks that do not set their own
+	Interval time.Duration `yaml:"interval"`
+	// StateFile defaults to ~/.clanker/watch/state.json
+	StateFile string         `yaml:"state_file"`
+	Notify    []NotifyConfig `yaml:"notify"`
+	Checks    []CheckConfig  `yaml:"checks"`
+}
+
+// CheckConfig is one check. Which fields apply depends on Type.
+type CheckConfig struct {
+	Name     string        `yaml:"name"`
+	Type     string        `yaml:"type"`
+	Interval time.Duration `yaml:"interval"`
+	// Namespace limits cluster-health to one namespace; Context picks the
+	// kubeconfig context
+	Namespace string `yaml:"namespace"`
+	Context   string `yaml:"context"`
+	// Days is the cert-expiry warning threshold
+	Days int `yaml:"days"`
+	// Sources limits cert-expiry to acm, cloudflare, gcp or cert-manager
+	Sources []string `yaml:"sources"`
+	// Threshold is the cost-anomaly percent change that counts
+	Threshold float64 `yaml:"threshold"`
+	// Profile is the AWS profile of cert-expiry and cost-anomaly
+	Profile string `yaml:"profile"`
+	// Repo and Branch scope failing-workflows
+	Repo   string `yaml:"repo"`
+	Branch string `yaml:"branch"`
+}
+
+// NotifyConfig is one notification target: stdout, webhook, slack or
+// discord
+type NotifyConfig struct {
+	Type string `yaml:"type"`
+	URL  string `yaml:"url"`
+}
+
+// LoadConfig reads and validates a checks file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checks file: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse checks file %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Checks) == 0 {
+		return fmt.Errorf("no checks configured")
+	}
+	seen := map[string]bool{}
+	for i, check := range c.Checks {
+		if strings.TrimSpace(check.Name) == "" {
+			return fmt.Errorf("check %d has no name", i+1)
+		}
+		if seen[check.Name] {
+			return fmt.Errorf("check name %q is used twice", check.Name)
+		}
+		seen[check.Name] = true
+		switch check.Type {
+		case TypeClusterHealth, TypeCertExpiry, TypeCostAnomaly, TypeFailingWorkflows:
+		default:
+			return fmt.Errorf("check %s has unknown type %q (use %s, %s, %s or %s)", check.Name, check.Type,
+				TypeClusterHealth, TypeCertExpiry, TypeCostAnomaly, TypeFailingWorkflows)
+		}
+		if interval := c.interval(check); interval < minInterval {
+			return fmt.Errorf("check %s runs every %s; the minimum is %s", check.Name, interval, minInterval)
+		}
+	}
+	for _, n := range c.Notify {
+		switch n.Type {
+		case "stdout":
+		case "webhook", "slack", "discord":
+			if strings.TrimSpace(n.URL) == "" {
+				return fmt.Errorf("%s notification needs a url", n.Type)
+			}
+		default:
+			return fmt.Errorf("unknown notification type %q (use stdout, webhook, slack or discord)", n.Type)
+		}
+	}
+	return nil
+}
+
+// interval is how often check runs
+func (c *Config) interval(check CheckConfig) time.Duration {
+	switch {
+	case check.Interval > 0:
+		return check.Interval
+	case c.Interval > 0:
+		return c.Interval
+	}
+	return DefaultInterval
+}
+
+// Result is what one check run found
+type Result struct {
+	Status  Status   `json:"status"`
+	Summary string   `json:"summary"`
+	Details []string `json:"details,omitempty"`
+}
+
+// Checker runs one type of check
+type Checker func(ctx context.Context, check CheckConfig) (Result, error)
+
+// CheckState is the last known status of a check
+type CheckState struct {
+	Status  Status `json:"status"`
+	Summary string `json:"summary"`
+	// Since is when the check entered Status
+	Since   time.Time `json:"since"`
+	LastRun time.Time `json:"lastRun"`
+}
+
+// State is the last known status of every check, by name
+type State struct {
+	Checks map[string]*CheckState `json:"checks"`
+}
+
+// DefaultStatePath returns ~/.clanker/watch/state.json
+func DefaultStatePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".clanker", "watch", "state.json"), nil
+}
+
+// LoadState reads the state file; a missing file is an empty state
+func LoadState(path string) (*State, error) {
+	state := &State{Checks: map[string]*CheckState{}}
+	data, err := secfile.ReadPrivate(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read watch state: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse watch state %s: %w", path, err)
+	}
+	if state.Checks == nil {
+		state.Checks = map[string]*CheckState{}
+	}
+	return state, nil
+}
+
+// Save writes the state file
+func (s *State) Save(path string) error {
+	if err := secfile.EnsurePrivateDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to create watch state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal watch state: %w", err)
+	}
+	if err := secfile.WritePrivate(path, data); err != nil {
+		return fmt.Errorf("failed to write watch state: %w", err)
+	}
+	return nil
+}
+
+// Transition is a check changing status
+type Transition struct {
+	Check   string    `json:"check"`
+	Type    string    `json:"type"`
+	From    Status    `json:"from,omitempty"` // empty the first time a check runs
+	To      Status    `json:"to"`
+	Summary string    `json:"summary"`
+	Details []string  `json:"details,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Watcher runs the checks of a config and notifies on transitions
+type Watcher struct {
+	cfg       *Config
+	checkers  map[string]Checker
+	notifiers []Notifier
+	state     *State
+	statePath string
+	debug     bool
+}
+
+// New creates a watcher. checkers maps each check type to the function that
+// runs it; state is saved to statePath after every run.
+func New(cfg *Config, checkers map[string]Checker, notifiers []Notifier, state *State, statePath string, debug bool) *Watcher {
+	return &Watcher{cfg: cfg, checkers: checkers, notifiers: notifiers, state: state, statePath: statePath, debug: debug}
+}
+
+// RunDue runs every check whose interval has passed since its last run,
+// records the results and notifies on status changes. A check seen for the
+// first time notifies only when it is not ok.
+func (w *Watcher) RunDue(ctx context.Context, now time.Time) []Transition {
+	var transitions []Transition
+	for _, check := range w.cfg.Checks {
+		prev := w.state.Checks[check.Name]
+		if prev != nil && now.Sub(prev.LastRun) < w.cfg.interval(check) {
+			continue
+		}
+
+		result := w.run(ctx, check)
+		if w.debug {
+			fmt.Printf("[watch] %s: %s (%s)\n", check.Name, result.Status, result.Summary)
+		}
+		next := &CheckState{Status: result.Status, Summary: result.Summary, Since: now.UTC(), LastRun: now.UTC()}
+		if prev != nil && prev.Status == result.Status {
+			next.Since = prev.Since
+		}
+		w.state.Checks[check.Name] = next
+
+		if (prev == nil && result.Status == StatusOK) || (prev != nil && prev.Status == result.Status) {
+			continue
+		}
+		t := Transition{Check: check.Name, Type: check.Type, To: result.Status, Summary: result.Summary, Details: result.Details, At: now.UTC()}
+		if prev != nil {
+			t.From = prev.Status
+		}
+		transitions = append(transitions, t)
+		for _, n := range w.notifiers {
+			if err := n.Notify(ctx, t); err != nil {
+				fmt.Fprintf(os.Stderr, "[watch] warning: notification failed: %v\n", err)
+			}
+		}
+	}
+	if err := w.state.Save(w.statePath); err != nil {
+		fmt.Fprintf(os.Stderr, "[watch] warning: %v\n", err)
+	}
+	return transitions
+}
+
+func (w *Watcher) run(ctx context.Context, check CheckConfig) Result {
+	checker := w.checkers[check.Type]
+	if checker == nil {
+		return Result{Status: StatusError, Summary: fmt.Sprintf("no checker for type %s", check.Type)}
+	}
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.interval(check))
+	defer cancel()
+	result, err := checker(ctx, check)
+	if err != nil {
+		return Result{Status: StatusError, Summary: err.Error()}
+	}
+	return result
+}
+
+// NextRun is when the next check is due
+func (w *Watcher) NextRun(now time.Time) time.Time {
+	next := time.Time{}
+	for _, check := range w.cfg.Checks {
+		due := now
+		if prev := w.state.Checks[check.Name]; prev != nil {
+			due = prev.LastRun.Add(w.cfg.interval(check))
+		}
+		if next.IsZero() || due.Before(next) {
+			next = due
+		}
+	}
+	return next
+}
+
+// Run runs checks as they fall due until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) error {
+	for {
+		w.RunDue(ctx, time.Now())
+		wait := time.Until(w.NextRun(time.Now()))
+		if wait < time.Second {
+			wait = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Statuses returns the last status of every configured check, in config
+// order
+func (w *Watcher) Statuses() []NamedState {
+	out := make([]NamedState, 0, len(w.cfg.Checks))
+	for _, check := range w.cfg.Checks {
+		if s := w.state.Checks[check.Name]; s != nil {
+			out = append(out, NamedState{Name: check.Name, CheckState: *s})
+		}
+	}
+	return out
+}
+
+// NamedState is a check's state with its name
+type NamedState struct {
+	Name string
+	CheckState
+}
+
+// worse orders statuses from ok to critical so checks can report their
+// worst finding
+func worse(a, b Status) Status {
+	rank := map[Status]int{StatusOK: 0, StatusWarning: 1, StatusError: 2, StatusCritical: 3}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,243 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgdnvk/clanker/internal/certs"
+	"github.com/bgdnvk/clanker/internal/cost"
+	"github.com/bgdnvk/clanker/internal/k8s/sre"
+)
+
+var testNow = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checks.yaml")
+	os.WriteFile(path, []byte(`interval: 2m
+notify:
+  - type: slack
+    url: https://hooks.slack.com/services/x
+checks:
+  - name: prod
+    type: cluster-health
+  - name: certs
+    type: cert-expiry
+    interval: 6h
+    days: 21
+`), 0o600)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.interval(cfg.Checks[0]) != 2*time.Minute || cfg.interval(cfg.Checks[1]) != 6*time.Hour || cfg.Checks[1].Days != 21 {
+		t.Errorf("config = %+v", cfg)
+	}
+
+	for body, want := range map[string]string{
+		"checks: []":                             "no checks configured",
+		"checks:\n  - name: a\n    type: uptime": `unknown type "uptime"`,
+		"checks:\n  - name: a\n    type: cert-expiry\n    interval: 5s":           "minimum is 30s",
+		"notify:\n  - type: webhook\nchecks:\n  - name: a\n    type: cert-expiry": "webhook notification needs a url",
+	} {
+		os.WriteFile(path, []byte(body), 0o600)
+		if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadConfig(%q) error = %v, want %q", body, err, want)
+		}
+	}
+}
+
+type recorder struct {
+	got []Transition
+}
+
+func (r *recorder) Notify(ctx context.Context, t Transition) error {
+	r.got = append(r.got, t)
+	return nil
+}
+
+func TestRunDueNotifiesOnTransitions(t *testing.T) {
+	statuses := []Status{StatusOK, StatusOK, StatusCritical, StatusCritical, StatusOK}
+	runs := 0
+	checker := func(ctx context.Context, check CheckConfig) (Result, error) {
+		s := statuses[runs]
+		runs++
+		return Result{Status: s, Summary: string(s)}, nil
+	}
+	failing := func(ctx context.Context, check CheckConfig) (Result, error) {
+		return Result{}, fmt.Errorf("gh not installed")
+	}
+	cfg := &Config{Interval: time.Minute, Checks: []CheckConfig{
+		{Name: "prod", Type: TypeClusterHealth},
+		{Name: "ci", Type: TypeFailingWorkflows, Interval: time.Hour},
+	}}
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	rec := &recorder{}
+	w := New(cfg, map[string]Checker{TypeClusterHealth: checker, TypeFailingWorkflows: failing}, []Notifier{rec}, &State{Checks: map[string]*CheckState{}}, statePath, false)
+
+	for i := 0; i < 5; i++ {
+		w.RunDue(context.Background(), testNow.Add(time.Duration(i)*time.Minute))
+	}
+	if runs != 5 {
+		t.Errorf("cluster check ran %d times, want 5", runs)
+	}
+	var got []string
+	for _, tr := range rec.got {
+		got = append(got, fmt.Sprintf("%s:%s->%s", tr.Check, tr.From, tr.To))
+	}
+	// a new check that is ok stays quiet; the failing check runs once an hour
+	want := "ci:->error prod:ok->critical prod:critical->ok"
+	if strings.Join(got, " ") != want {
+		t.Errorf("transitions = %v, want %s", got, want)
+	}
+
+	// a restart picks up the saved state and does not announce it again
+	state, err := LoadState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := state.Checks["prod"]; s.Status != StatusOK || !s.Since.Equal(testNow.Add(4*time.Minute)) {
+		t.Errorf("saved prod state = %+v", s)
+	}
+	if s := state.Checks["ci"]; s.Status != StatusError || s.Summary != "gh not installed" {
+		t.Errorf("saved ci state = %+v", s)
+	}
+	rec.got = nil
+	statuses = append(statuses, StatusOK)
+	w = New(cfg, map[string]Checker{TypeClusterHealth: checker, TypeFailingWorkflows: failing}, []Notifier{rec}, state, statePath, false)
+	w.RunDue(context.Background(), testNow.Add(5*time.Minute))
+	if len(rec.got) != 0 {
+		t.Errorf("restart notified %+v", rec.got)
+	}
+	if next := w.NextRun(testNow.Add(5 * time.Minute)); !next.Equal(testNow.Add(6 * time.Minute)) {
+		t.Errorf("next run = %s", next)
+	}
+}
+
+type fakeHealth struct{}
+
+func (fakeHealth) CheckClusterDetailed(ctx context.Context) (*sre.ClusterHealthSummary, []sre.Issue, error) {
+	return &sre.ClusterHealthSummary{OverallHealth: "degraded", Score: 70, WarningIssues: 1},
+		[]sre.Issue{{Severity: sre.SeverityWarning, ResourceType: "deployment", Namespace: "shop", ResourceName: "api", Message: "1/3 replicas ready"}}, nil
+}
+
+func (fakeHealth) CheckNamespace(ctx context.Context, namespace string) (*sre.HealthCheckResult, error) {
+	return &sre.HealthCheckResult{Healthy: true, Score: 100}, nil
+}
+
+func TestClusterHealth(t *testing.T) {
+	var gotContext string
+	check := ClusterHealth(func(kubeContext string) HealthAPI {
+		gotContext = kubeContext
+		return fakeHealth{}
+	})
+	r, err := check(context.Background(), CheckConfig{Context: "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotContext != "prod" || r.Status != StatusWarning || len(r.Details) != 1 || r.Details[0] != "WARNING deployment/shop/api: 1/3 replicas ready" {
+		t.Errorf("context = %q, result = %+v", gotContext, r)
+	}
+	if r, _ := check(context.Background(), CheckConfig{Namespace: "shop"}); r.Status != StatusOK {
+		t.Errorf("namespace result = %+v", r)
+	}
+}
+
+func TestCertExpiry(t *testing.T) {
+	var gotSources []certs.Source
+	check := CertExpiry(func(ctx context.Context, profile string, sources []certs.Source) *certs.Report {
+		gotSources = sources
+		return &certs.Report{Sources: []certs.Source{certs.ACM, certs.CertManager}, Errors: map[certs.Source]error{certs.CertManager: fmt.Errorf("no cluster")},
+			Certs: []certs.Cert{
+				{Source: certs.ACM, Name: "api.example.com", NotAfter: testNow.Add(20 * 24 * time.Hour)},
+				{Source: certs.ACM, Name: "www.example.com", NotAfter: testNow.Add(20 * 24 * time.Hour), AutoRenew: true},
+				{Source: certs.ACM, Name: "old.example.com", NotAfter: testNow.Add(90 * 24 * time.Hour)},
+			}}
+	}, func() time.Time { return testNow })
+
+	r, err := check(context.Background(), CheckConfig{Sources: []string{"ACM", "cert-manager"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotSources) != 2 || gotSources[0] != certs.ACM {
+		t.Errorf("sources = %v", gotSources)
+	}
+	if r.Status != StatusWarning || r.Summary != "1 of 3 certificates need attention within 30 days" {
+		t.Errorf("result = %+v", r)
+	}
+	if len(r.Details) != 2 || r.Details[0] != "ACM api.example.com: expires in 20 days" || !strings.Contains(r.Details[1], "cert-manager could not be read") {
+		t.Errorf("details = %v", r.Details)
+	}
+}
+
+func TestCostAnomaly(t *testing.T) {
+	check := CostAnomaly(func(ctx context.Context, profile string) ([]cost.CostAnomaly, error) {
+		return []cost.CostAnomaly{
+			{Service: "EC2", ExpectedCost: 100, ActualCost: 150, PercentChange: 50},
+			{Service: "S3", ExpectedCost: 10, ActualCost: 11, PercentChange: 10},
+		}, nil
+	})
+	if r, _ := check(context.Background(), CheckConfig{}); r.Status != StatusCritical || len(r.Details) != 1 {
+		t.Errorf("default threshold result = %+v", r)
+	}
+	if r, _ := check(context.Background(), CheckConfig{Threshold: 30}); r.Status != StatusWarning {
+		t.Errorf("threshold 30 result = %+v", r)
+	}
+}
+
+func TestFailingWorkflows(t *testing.T) {
+	var gotArgs []string
+	check := FailingWorkflows(func(ctx context.Context, args []string) (string, error) {
+		gotArgs = args
+		return `[{"workflowName":"CI","headBranch":"main","status":"in_progress","conclusion":""},
+			{"workflowName":"CI","headBranch":"main","status":"completed","conclusion":"failure","url":"https://github.com/acme/api/actions/runs/2"},
+			{"workflowName":"CI","headBranch":"main","status":"completed","conclusion":"success"},
+			{"workflowName":"Deploy","headBranch":"main","status":"completed","conclusion":"success"}]`, nil
+	})
+	r, err := check(context.Background(), CheckConfig{Repo: "acme/api", Branch: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(gotArgs, " "), "-R acme/api --branch main") {
+		t.Errorf("args = %v", gotArgs)
+	}
+	if r.Status != StatusCritical || r.Summary != "1 of 2 workflows failing" || !strings.HasPrefix(r.Details[0], "CI on main: failure") {
+		t.Errorf("result = %+v", r)
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+	}))
+	defer srv.Close()
+
+	var out strings.Builder
+	notifiers := NewNotifiers([]NotifyConfig{{Type: "stdout"}, {Type: "webhook", URL: srv.URL}, {Type: "slack", URL: srv.URL}}, &out)
+	tr := Transition{Check: "certs", Type: TypeCertExpiry, From: StatusOK, To: StatusWarning, Summary: "1 of 3 certificates", Details: []string{"ACM api: expires in 20 days"}, At: testNow}
+	for _, n := range notifiers {
+		if err := n.Notify(context.Background(), tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.Contains(out.String(), "certs: ok -> WARNING: 1 of 3 certificates\n  - ACM api") {
+		t.Errorf("stdout = %q", out.String())
+	}
+	var hook Transition
+	if len(bodies) != 2 || json.Unmarshal([]byte(bodies[0]), &hook) != nil || hook.To != StatusWarning {
+		t.Fatalf("bodies = %v", bodies)
+	}
+	if !strings.Contains(bodies[1], `"text":"certs is WARNING (was ok)"`) {
+		t.Errorf("slack body = %s", bodies[1])
+	}
+}